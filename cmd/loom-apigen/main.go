@@ -0,0 +1,48 @@
+// Command loom-apigen writes the OpenAPI document and the Go client that
+// are generated from the server's route table (internal/api/openapi.go).
+//
+//	go generate ./internal/api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/jordanhubbard/loom/internal/api"
+)
+
+func main() {
+	specPath := flag.String("spec", "api/openapi.json", "where to write the OpenAPI document")
+	yamlPath := flag.String("yaml", "api/openapi.yaml", "where to write the OpenAPI document as YAML")
+	clientPath := flag.String("client", "pkg/client/zz_generated.go", "where to write the generated client")
+	flag.Parse()
+
+	spec, err := api.OpenAPIDocument()
+	if err != nil {
+		fail(err)
+	}
+	specYAML, err := api.OpenAPIYAML()
+	if err != nil {
+		fail(err)
+	}
+	client, err := api.GenerateClient()
+	if err != nil {
+		fail(err)
+	}
+
+	if err := os.WriteFile(*specPath, append(spec, '\n'), 0644); err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(*yamlPath, specYAML, 0644); err != nil {
+		fail(err)
+	}
+	if err := os.WriteFile(*clientPath, client, 0644); err != nil {
+		fail(err)
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "loom-apigen: %v\n", err)
+	os.Exit(1)
+}
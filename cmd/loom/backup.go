@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jordanhubbard/loom/internal/backup"
+	"github.com/jordanhubbard/loom/internal/keymanager"
+	"github.com/jordanhubbard/loom/internal/loom"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// runBackup runs the backup subcommand against the configured database and
+// key store:
+//
+//	loom backup list         - List backups, newest first
+//	loom backup create       - Back up the database and key store now
+//	loom backup verify ID    - Check a backup's checksums and database dump
+//	loom backup restore ID   - Replace the database and key store with a backup
+//
+// Restore overwrites the live database, so the server must be stopped first.
+func runBackup(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	mgr := loom.NewBackups(db, cfg.Backup)
+	mgr.SetKeystore(keymanager.NewKeyManager(keyStorePath))
+	ctx := context.Background()
+
+	switch args[0] {
+	case "list":
+		return printBackups(mgr)
+	case "create":
+		b, err := mgr.Create(ctx, backup.TriggerManual, "cli")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created backup %s (%d bytes, schema version %d) in %s\n", b.ID, b.SizeBytes, b.SchemaVersion, mgr.Dir(b.ID))
+		return nil
+	case "verify", "restore":
+		if len(args) < 2 {
+			return fmt.Errorf("%s takes a backup ID", args[0])
+		}
+		if args[0] == "restore" {
+			pre, err := mgr.Restore(ctx, args[1])
+			if pre != nil {
+				fmt.Printf("Saved the previous state as backup %s\n", pre.ID)
+			}
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restored backup %s\n", args[1])
+			return nil
+		}
+		v, err := mgr.Verify(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		if !v.OK {
+			return fmt.Errorf("backup %s failed verification: %s", args[1], strings.Join(v.Problems, "; "))
+		}
+		fmt.Printf("Backup %s is intact\n", args[1])
+		return nil
+	default:
+		return fmt.Errorf("unknown backup command %q", args[0])
+	}
+}
+
+// printBackups lists every backup and the outcome of its latest check
+func printBackups(mgr *backup.Manager) error {
+	list, err := mgr.List()
+	if err != nil {
+		return err
+	}
+	if len(list) == 0 {
+		fmt.Println("No backups")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTRIGGER\tCREATED AT\tSCHEMA\tSIZE\tVERIFIED")
+	for _, b := range list {
+		verified := "never"
+		if v := b.Verification; v != nil {
+			verified = "failed"
+			if v.OK {
+				verified = "ok"
+			}
+			verified += " " + v.VerifiedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%04d\t%d\t%s\n", b.ID, b.Trigger, b.CreatedAt.Local().Format("2006-01-02 15:04:05"), b.SchemaVersion, b.SizeBytes, verified)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// runConfig runs the config subcommand against the config file at path,
+// which is empty when none was found:
+//
+//	loom config validate  - Load the configuration strictly and list every problem
+//	loom config env       - List the environment variables that override settings
+func runConfig(path string, args []string) error {
+	if len(args) == 0 {
+		args = []string{"validate"}
+	}
+
+	switch args[0] {
+	case "validate":
+		if _, err := config.Check(path); err != nil {
+			return err
+		}
+		if path == "" {
+			fmt.Println("No config file found; the defaults and environment are valid")
+			return nil
+		}
+		fmt.Printf("%s is valid\n", path)
+		return nil
+	case "env":
+		vars := config.EnvVars()
+		names := make([]string, 0, len(vars))
+		for name := range vars {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VARIABLE\tSETTING\tSET")
+		for _, name := range names {
+			set := ""
+			if os.Getenv(name) != "" {
+				set = "yes"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", name, vars[name], set)
+		}
+		return w.Flush()
+	default:
+		return fmt.Errorf("unknown config command %q", args[0])
+	}
+}
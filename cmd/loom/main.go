@@ -8,7 +8,6 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"strings"
 	"syscall"
 	"time"
@@ -17,16 +16,19 @@ import (
 	"github.com/jordanhubbard/loom/internal/api"
 	"github.com/jordanhubbard/loom/internal/auth"
 	"github.com/jordanhubbard/loom/internal/hotreload"
-	"github.com/jordanhubbard/loom/internal/keymanager"
+	"github.com/jordanhubbard/loom/internal/logging"
 	"github.com/jordanhubbard/loom/pkg/config"
 )
 
 const version = "0.1.0"
 
+// keyStorePath is the encrypted key store, relative to the working directory
+const keyStorePath = ".keys.json"
+
 func main() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	configPath := flag.String("config", "config.yaml", "Path to configuration file")
+	configPath := flag.String("config", "", "Path to a YAML or TOML configuration file")
 	showVersion := flag.Bool("version", false, "Show version information")
 	showHelp := flag.Bool("help", false, "Show help message")
 	flag.Parse()
@@ -41,45 +43,81 @@ func main() {
 		return
 	}
 
-	cfg, err := config.LoadConfigFromFile(*configPath)
+	path, err := config.Find(*configPath)
 	if err != nil {
-		log.Fatalf("failed to load config from %s: %v", *configPath, err)
+		log.Fatalf("%v", err)
+	}
+	if flag.Arg(0) == "config" {
+		if err := runConfig(path, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	// Override with environment variables if set
-	if temporalHost := os.Getenv("TEMPORAL_HOST"); temporalHost != "" {
-		cfg.Temporal.Host = temporalHost
-		log.Printf("Using Temporal host from environment: %s", temporalHost)
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
 	}
-	if temporalNamespace := os.Getenv("TEMPORAL_NAMESPACE"); temporalNamespace != "" {
-		cfg.Temporal.Namespace = temporalNamespace
-		log.Printf("Using Temporal namespace from environment: %s", temporalNamespace)
+	if err := logging.Configure(cfg.Logging.Format, cfg.Logging.Level, cfg.Logging.Modules); err != nil {
+		log.Fatalf("invalid logging config: %v", err)
 	}
 
-	arb, err := loom.New(cfg)
-	if err != nil {
-		log.Fatalf("failed to create loom: %v", err)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("migrate: %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "backup" {
+		if err := runBackup(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("backup: %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "secrets" {
+		if err := runSecrets(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("secrets: %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "workflows" {
+		if err := runWorkflows(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("workflows: %v", err)
+		}
+		return
+	}
+	if flag.Arg(0) == "tui" {
+		if err := runTUI(cfg, flag.Args()[1:]); err != nil {
+			log.Fatalf("tui: %v", err)
+		}
+		return
 	}
 
-	// Initialize key manager before Loom.Initialize() so Temporal activities
-	// can use it for provider API key retrieval during heartbeats.
-	keyStorePath := filepath.Join(".", ".keys.json")
-	km := keymanager.NewKeyManager(keyStorePath)
+	if path == "" {
+		log.Printf("No config file found; using defaults and environment")
+	} else {
+		log.Printf("Loaded configuration from %s", path)
+	}
 
-	password := loadPassword()
-	if password == "" {
-		log.Printf("Warning: No password found. Using default password. Set LOOM_PASSWORD environment variable or create .env file")
-		password = "loom-default-password"
+	// Unlock the key store before Loom is created so secret:NAME settings
+	// resolve, and so Temporal activities can use it for provider API key
+	// retrieval during heartbeats.
+	km, err := unlockKeyStore(cfg)
+	if err != nil {
+		log.Fatalf("Failed to unlock key store: %v", err)
+	}
+	if err := cfg.ResolveSecrets(km.GetKey); err != nil {
+		log.Fatalf("%v", err)
 	}
 
-	if err := km.Unlock(password); err != nil {
-		log.Printf("Password unlock failed: %v. Trying default password...", err)
-		if err := km.Unlock("loom-default-password"); err != nil {
-			log.Fatalf("Failed to unlock key manager with both passwords: %v", err)
-		}
+	arb, err := loom.New(cfg)
+	if err != nil {
+		log.Fatalf("failed to create loom: %v", err)
 	}
 
 	arb.SetKeyManager(km)
+	arb.SetConfigPath(path)
 
 	runCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -110,8 +148,23 @@ func main() {
 
 	// Initialize auth manager (JWT + API key support)
 	authManager := auth.NewManager(cfg.Security.JWTSecret)
+	if db := arb.GetDatabase(); db != nil {
+		if err := authManager.SetAPIKeyStore(db); err != nil {
+			log.Printf("Warning: API keys will not persist across restarts: %v", err)
+		}
+	}
+	authManager.SetOrgs(arb.GetOrgManager())
+	authManager.SetProjectOrgResolver(arb.ProjectOrg)
+	arb.SetDeciderAuthorizer(authManager.UserAuthorized)
+	if cfg.Security.OIDC.Enabled {
+		if err := authManager.EnableOIDC(cfg.Security.OIDC); err != nil {
+			log.Fatalf("Failed to configure SSO: %v", err)
+		}
+		log.Printf("SSO enabled via %s", cfg.Security.OIDC.IssuerURL)
+	}
 
 	apiServer := api.NewServer(arb, km, authManager, cfg)
+	apiServer.StartCacheWarmer(runCtx)
 	handler := apiServer.SetupRoutes()
 
 	// Add hot-reload WebSocket endpoint if enabled
@@ -139,9 +192,20 @@ func main() {
 		}
 	}()
 
+	// SIGHUP reloads the settings that can change without a restart
+	hupCh := make(chan os.Signal, 1)
+	signal.Notify(hupCh, syscall.SIGHUP)
+	go func() {
+		for range hupCh {
+			log.Printf("Received SIGHUP; reloading configuration")
+			_, _ = arb.ReloadConfig(runCtx, "signal:SIGHUP") // Logged and audited either way
+		}
+	}()
+
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	<-sigCh
+	signal.Stop(hupCh)
 	cancel()
 
 	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -180,13 +244,49 @@ func loadPassword() string {
 }
 
 func printHelp() {
-	fmt.Println("Usage: loom [flags]")
+	fmt.Println("Usage: loom [flags] [command]")
 	fmt.Println()
 	fmt.Println("Flags:")
-	fmt.Println("  -config   Path to configuration file (default: config.yaml)")
+	fmt.Println("  -config   Path to a YAML or TOML configuration file (default: the first")
+	fmt.Println("            config.yaml, config.yml or config.toml found in ., the user")
+	fmt.Println("            config directory under loom/, then /etc/loom)")
 	fmt.Println("  -version  Show version information")
 	fmt.Println("  -help     Show help message")
 	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  migrate status      List schema migrations and which are applied")
+	fmt.Println("  migrate up          Apply pending migrations")
+	fmt.Println("  migrate down [N]    Revert the newest N migrations (default 1)")
+	fmt.Println("  migrate to V        Apply or revert until V is the newest applied")
+	fmt.Println("  migrate force V     Record V as applied and clear the dirty flag")
+	fmt.Println("  backup list         List backups, newest first")
+	fmt.Println("  backup create       Back up the database and key store now")
+	fmt.Println("  backup verify ID    Check a backup's checksums and database dump")
+	fmt.Println("  backup restore ID   Restore a backup; stop the server first")
+	fmt.Println("  secrets list        List stored secrets and the settings using them")
+	fmt.Println("  secrets set NAME    Store a secret read from standard input")
+	fmt.Println("  secrets delete NAME Remove a secret")
+	fmt.Println("  secrets status      Show which backend wraps the key store's data key")
+	fmt.Println("  workflows replay PATH...")
+	fmt.Println("                      Replay exported workflow histories against this build")
+	fmt.Println("  workflows check     Replay every running Temporal workflow against this build")
+	fmt.Println("  workflows export DIR")
+	fmt.Println("                      Save the history of every running workflow to DIR")
+	fmt.Println("  tui [URL]           Operator terminal UI for a running server (default:")
+	fmt.Println("                      localhost on server.http_port)")
+	fmt.Println("  config validate     Check the configuration and report every problem")
+	fmt.Println("  config env          List the LOOM_ variables that override settings")
+	fmt.Println()
+	fmt.Println("Without a command, loom starts the server; pending migrations are")
+	fmt.Println("applied automatically at startup. Send the server SIGHUP to reload the")
+	fmt.Println("providers, agents.max_concurrent, dispatch, readiness, logging and backup")
+	fmt.Println("settings from the configuration file without a restart. A setting written")
+	fmt.Println("as secret:NAME is read from the key store entry NAME.")
+	fmt.Println()
 	fmt.Println("Environment:")
 	fmt.Println("  LOOM_PASSWORD  Master password for UI login and key encryption")
+	fmt.Println("  LOOM_CONFIG    Configuration file, when -config is not given")
+	fmt.Println("  LOOM_TOKEN     JWT or API key the tui command authenticates with")
+	fmt.Println("  LOOM_<PATH>    Overrides a setting, e.g. LOOM_SERVER_HTTP_PORT=9090;")
+	fmt.Println("                 these take precedence over the configuration file")
 }
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/migrate"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// runMigrate runs the migrate subcommand against the configured database:
+//
+//	loom migrate status      - List migrations and which are applied
+//	loom migrate up          - Apply every pending migration
+//	loom migrate down [N]    - Revert the newest N migrations (default 1)
+//	loom migrate to V        - Apply or revert until V is the newest applied
+//	loom migrate force V     - Record V as applied and clear the dirty flag
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		args = []string{"status"}
+	}
+
+	db, err := openDatabase(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	m, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	var ran []migrate.Migration
+	switch args[0] {
+	case "status":
+		return printMigrationStatus(ctx, m)
+	case "up":
+		ran, err = m.Up(ctx)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps <= 0 {
+				return fmt.Errorf("down takes a positive number of steps")
+			}
+		}
+		ran, err = m.Down(ctx, steps)
+	case "to", "force":
+		if len(args) < 2 {
+			return fmt.Errorf("%s takes a version", args[0])
+		}
+		version, perr := strconv.ParseInt(args[1], 10, 64)
+		if perr != nil || version < 0 {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		if args[0] == "force" {
+			if err := m.Force(ctx, version); err != nil {
+				return err
+			}
+			fmt.Printf("Forced version %d\n", version)
+			return nil
+		}
+		ran, err = m.To(ctx, version)
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	for _, mig := range ran {
+		fmt.Printf("%04d_%s\n", mig.Version, mig.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(ran) == 0 {
+		fmt.Println("Nothing to do")
+	}
+	return nil
+}
+
+// openDatabase opens the configured database without migrating it
+func openDatabase(cfg *config.Config) (*database.Database, error) {
+	switch {
+	case cfg.Database.Type == "sqlite" && cfg.Database.Path != "":
+		return database.Open(cfg.Database.Path)
+	case cfg.Database.Type == "postgres" && cfg.Database.DSN != "":
+		return database.OpenPostgres(cfg.Database.DSN, database.PoolConfig{MaxOpenConns: 1})
+	default:
+		return nil, fmt.Errorf("no database configured")
+	}
+}
+
+// printMigrationStatus lists every migration and whether it is applied
+func printMigrationStatus(ctx context.Context, m *migrate.Migrator) error {
+	states, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, st := range states {
+		status, appliedAt := "pending", ""
+		if st.Applied {
+			status, appliedAt = "applied", st.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		if st.Dirty {
+			status = "dirty"
+		}
+		fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", st.Version, st.Name, status, appliedAt)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nVersion %d of %d\n", version, m.Latest())
+	for _, st := range states {
+		if st.Dirty {
+			fmt.Println(&migrate.DirtyError{Version: st.Version})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jordanhubbard/loom/internal/keymanager"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// defaultPassword unlocks the key store when LOOM_PASSWORD is not set
+const defaultPassword = "loom-default-password"
+
+// unlockKeyStore opens the key store with the configured key wrapper and
+// the master password, falling back to the default password
+func unlockKeyStore(cfg *config.Config) (*keymanager.KeyManager, error) {
+	w, err := keymanager.NewWrapper(cfg.Security.KeyStore)
+	if err != nil {
+		return nil, err
+	}
+	km := keymanager.NewKeyManager(keyStorePath)
+	km.SetWrapper(w)
+
+	password := loadPassword()
+	if password == "" {
+		log.Printf("Warning: No password found. Using default password. Set LOOM_PASSWORD environment variable or create .env file")
+		password = defaultPassword
+	}
+	if err := km.Unlock(password); err != nil {
+		if password == defaultPassword {
+			return nil, err
+		}
+		log.Printf("Password unlock failed: %v. Trying default password...", err)
+		if err := km.Unlock(defaultPassword); err != nil {
+			return nil, fmt.Errorf("failed to unlock key manager with both passwords: %w", err)
+		}
+	}
+	return km, nil
+}
+
+// runSecrets runs the secrets subcommand against the key store. Settings
+// written as secret:NAME are read from these entries at startup.
+//
+//	loom secrets list          - List stored secrets and the settings using them
+//	loom secrets set NAME      - Store the value read from standard input
+//	loom secrets delete NAME   - Remove a secret
+//	loom secrets status        - Show how the key store's data key is wrapped
+func runSecrets(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		args = []string{"list"}
+	}
+
+	km, err := unlockKeyStore(cfg)
+	if err != nil {
+		return err
+	}
+	defer km.Lock()
+
+	switch args[0] {
+	case "list":
+		return printSecrets(km, cfg)
+	case "set", "delete":
+		if len(args) < 2 {
+			return fmt.Errorf("%s takes a secret name", args[0])
+		}
+		name := args[1]
+		if args[0] == "delete" {
+			if err := km.DeleteKey(name); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted secret %s\n", name)
+			return nil
+		}
+		value, err := readSecret(os.Stdin)
+		if err != nil {
+			return err
+		}
+		if err := km.StoreKey(name, name, "", value); err != nil {
+			return err
+		}
+		fmt.Printf("Stored secret %s; reference it as %s%s\n", name, config.SecretPrefix, name)
+		return nil
+	case "status":
+		env := km.KeyEncryption()
+		if env == nil {
+			return fmt.Errorf("key store %s has no data key", keyStorePath)
+		}
+		fmt.Printf("Key store: %s\n", keyStorePath)
+		fmt.Printf("Backend:   %s\n", env.Backend)
+		if env.KeyID != "" {
+			fmt.Printf("Key:       %s\n", env.KeyID)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown secrets command %q (want list, set, delete or status)", args[0])
+}
+
+// readSecret reads a value from r up to the first newline
+func readSecret(r io.Reader) (string, error) {
+	if f, ok := r.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			fmt.Fprint(os.Stderr, "Value: ")
+		}
+	}
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	value := strings.TrimRight(line, "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("no value on standard input")
+	}
+	return value, nil
+}
+
+func printSecrets(km *keymanager.KeyManager, cfg *config.Config) error {
+	keys, err := km.ListKeys()
+	if err != nil {
+		return err
+	}
+	usedBy := map[string][]string{}
+	for field, name := range cfg.SecretRefs() {
+		usedBy[name] = append(usedBy[name], field)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tUPDATED\tUSED BY")
+	for _, k := range keys {
+		fields := usedBy[k.ID]
+		sort.Strings(fields)
+		delete(usedBy, k.ID)
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", k.ID, k.UpdatedAt.Format("2006-01-02 15:04"), strings.Join(fields, ", "))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	missing := make([]string, 0, len(usedBy))
+	for name := range usedBy {
+		missing = append(missing, name)
+	}
+	sort.Strings(missing)
+	for _, name := range missing {
+		fields := usedBy[name]
+		sort.Strings(fields)
+		fmt.Printf("Missing secret %s, referenced by %s\n", name, strings.Join(fields, ", "))
+	}
+	return nil
+}
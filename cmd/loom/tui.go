@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/jordanhubbard/loom/internal/tui"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// runTUI opens the terminal UI against a running server:
+//
+//	loom tui [URL]
+//
+// URL defaults to the configured HTTP port on localhost. LOOM_TOKEN, a JWT
+// or API key, authenticates the UI when the server requires it.
+func runTUI(cfg *config.Config, args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("tui takes at most a server URL")
+	}
+	url := fmt.Sprintf("http://localhost:%d", cfg.Server.HTTPPort)
+	if len(args) == 1 {
+		url = args[0]
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return tui.Run(ctx, url, os.Getenv("LOOM_TOKEN"))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/jordanhubbard/loom/internal/temporal"
+	temporalclient "github.com/jordanhubbard/loom/internal/temporal/client"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// runWorkflows checks that this build can continue the Temporal workflow
+// executions started by the build it replaces:
+//
+//	loom workflows replay PATH... - Replay exported histories (files, or directories of .json)
+//	loom workflows check          - Replay every running execution on the task queue
+//	loom workflows export DIR     - Save the history of every running execution to DIR
+//
+// check and export connect to the Temporal cluster in the configuration;
+// replay needs no connection.
+func runWorkflows(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("expected replay, check or export")
+	}
+
+	switch args[0] {
+	case "replay":
+		if len(args) < 2 {
+			return fmt.Errorf("replay takes history files or directories")
+		}
+		var results []temporal.ReplayResult
+		for _, path := range args[1:] {
+			files, err := historyFiles(path)
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				results = append(results, temporal.ReplayResult{File: file, Err: temporal.ReplayHistoryFile(file)})
+			}
+		}
+		return printReplayResults(results)
+	case "check", "export":
+		if cfg.Scheduler.Backend == "embedded" {
+			return fmt.Errorf("the embedded scheduler runs no Temporal workflows")
+		}
+		c, err := temporalclient.New(&cfg.Temporal)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		ctx := context.Background()
+
+		if args[0] == "check" {
+			results, err := temporal.ReplayRunning(ctx, c)
+			if err != nil {
+				return err
+			}
+			return printReplayResults(results)
+		}
+		if len(args) < 2 {
+			return fmt.Errorf("export takes a directory")
+		}
+		results, err := temporal.ExportRunning(ctx, c, args[1])
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			fmt.Printf("%s %s -> %s\n", r.WorkflowType, r.WorkflowID, r.File)
+		}
+		fmt.Printf("Exported %d histories\n", len(results))
+		return nil
+	default:
+		return fmt.Errorf("unknown workflows command %q", args[0])
+	}
+}
+
+// historyFiles returns path, or the .json files in it if it is a directory
+func historyFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	return filepath.Glob(filepath.Join(path, "*.json"))
+}
+
+// printReplayResults lists each replayed execution and fails if any of them
+// could not be continued by this build
+func printReplayResults(results []temporal.ReplayResult) error {
+	if len(results) == 0 {
+		fmt.Println("No workflow histories to replay")
+		return nil
+	}
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "WORKFLOW\tRESULT")
+	for _, r := range results {
+		name := r.File
+		if r.WorkflowID != "" {
+			name = fmt.Sprintf("%s (%s)", r.WorkflowID, r.WorkflowType)
+		}
+		result := "ok"
+		if r.Err != nil {
+			failed++
+			result = r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\n", name, result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d executions cannot be replayed by this build", failed, len(results))
+	}
+	return nil
+}
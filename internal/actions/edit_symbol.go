@@ -0,0 +1,123 @@
+package actions
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/codechunk"
+)
+
+// maxSymbolHintLines caps the current text of a definition quoted back to
+// the agent when its OLD text is not found
+const maxSymbolHintLines = 40
+
+// MatchAndReplaceInFile is MatchAndReplace for a file at path. When the old
+// text is a complete function, method or type that no longer matches the
+// file — typically because it was copied from a stale read — the current
+// definition of the same name is replaced as a whole ("symbol" strategy).
+func MatchAndReplaceInFile(path, content, oldText, newText string) (string, bool, string) {
+	if result, ok, strategy := MatchAndReplace(content, oldText, newText); ok {
+		return result, true, strategy
+	}
+	if result, ok := symbolMatch(path, content, oldText, newText); ok {
+		return result, true, "symbol"
+	}
+	return content, false, ""
+}
+
+// symbolMatch replaces the definition the old text defines, provided the
+// file has exactly one definition of that name and of similar size
+func symbolMatch(path, content, oldText, newText string) (string, bool) {
+	oldText = dedent(oldText)
+	old := definitions(codechunk.Split(path, oldText))
+	if len(old) != 1 || strings.TrimSpace(old[0].Text) != strings.TrimSpace(oldText) {
+		return "", false
+	}
+	current := codechunk.Find(codechunk.Split(path, content), old[0].Name)
+	if len(current) != 1 {
+		return "", false
+	}
+	target := current[0]
+	// Like block anchors, refuse a definition of very different size
+	if target.Lines() > old[0].Lines()*2 || old[0].Lines() > target.Lines()*2 {
+		return "", false
+	}
+	start := target.StartLine
+	if old[0].DeclLine == old[0].StartLine {
+		// The old text has no doc comment, so keep the current one
+		start = target.DeclLine
+	}
+	lines := strings.Split(content, "\n")
+	replaced := append(append(append([]string{}, lines[:start-1]...), strings.TrimRight(newText, "\n")), lines[target.EndLine:]...)
+	return strings.Join(replaced, "\n"), true
+}
+
+var identifier = regexp.MustCompile(`[A-Za-z_]\w*`)
+
+// SymbolHint quotes the current text of the definition an unmatched old
+// text most likely meant, so the agent can retry without another read.
+// It returns "" when no definition of the file is named in the old text.
+func SymbolHint(path, content, oldText string) string {
+	chunks := definitions(codechunk.Split(path, content))
+	if len(chunks) == 0 {
+		return ""
+	}
+	var names []string
+	if old := definitions(codechunk.Split(path, dedent(oldText))); len(old) > 0 {
+		names = append(names, old[0].Name)
+	}
+	names = append(names, identifier.FindAllString(oldText, -1)...)
+
+	for _, name := range names {
+		found := codechunk.Find(chunks, name)
+		if len(found) != 1 {
+			continue
+		}
+		c := found[0]
+		text := strings.Split(c.Text, "\n")
+		if len(text) > maxSymbolHintLines {
+			text = append(text[:maxSymbolHintLines], "...")
+		}
+		return fmt.Sprintf("Current text of %s %s (lines %d-%d):\n%s", c.Kind, c.Name, c.StartLine, c.EndLine, strings.Join(text, "\n"))
+	}
+	return ""
+}
+
+func definitions(chunks []codechunk.Chunk) []codechunk.Chunk {
+	var defs []codechunk.Chunk
+	for _, c := range chunks {
+		if c.Kind != codechunk.KindBlock {
+			defs = append(defs, c)
+		}
+	}
+	return defs
+}
+
+// dedent removes the indentation common to every non-blank line, so a
+// method copied out of a class parses as a top-level definition
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	if prefix == "" {
+		return text
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+	return strings.Join(lines, "\n")
+}
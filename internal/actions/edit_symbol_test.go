@@ -0,0 +1,78 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+)
+
+const symbolSource = `package calc
+
+// Add adds
+func Add(a, b int) int {
+	return a + b
+}
+
+func Sub(a, b int) int {
+	return a - b
+}
+`
+
+func TestMatchAndReplaceInFile_Symbol(t *testing.T) {
+	// The agent read Add before someone renamed its parameters
+	stale := "func Add(x, y int) int {\n\treturn x + y\n}"
+	updated := "func Add(a, b int) int {\n\treturn b + a\n}"
+	result, ok, strategy := MatchAndReplaceInFile("calc.go", symbolSource, stale, updated)
+	if !ok || strategy != "symbol" {
+		t.Fatalf("expected a symbol match, got %v %q", ok, strategy)
+	}
+	want := strings.Replace(symbolSource, "return a + b", "return b + a", 1)
+	if result != want {
+		t.Errorf("result:\n%s", result)
+	}
+
+	// Text that matches is still replaced exactly
+	if _, _, strategy := MatchAndReplaceInFile("calc.go", symbolSource, "return a - b", "return b - a"); strategy != "exact" {
+		t.Errorf("strategy = %q, want exact", strategy)
+	}
+}
+
+func TestMatchAndReplaceInFile_SymbolRefused(t *testing.T) {
+	tests := []struct {
+		name, path, old string
+	}{
+		{"part of a definition", "calc.go", "func Add(x, y int) int {\n\treturn x + y"},
+		{"unknown definition", "calc.go", "func Mul(a, b int) int {\n\treturn a * b\n}"},
+		{"much smaller definition", "calc.go", "func Add() {}"},
+		{"not source code", "calc.txt", "func Add(x, y int) int {\n\treturn x + y\n}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok, strategy := MatchAndReplaceInFile(tt.path, symbolSource, tt.old, "x"); ok {
+				t.Errorf("expected no match, got %q", strategy)
+			}
+		})
+	}
+}
+
+func TestMatchAndReplaceInFile_PythonMethod(t *testing.T) {
+	src := "class Store:\n    def get(self, key):\n        return self.items[key]\n\n    def put(self, key, value):\n        self.items[key] = value\n"
+	stale := "    def get(self, name):\n        return self.items[name]"
+	updated := "    def get(self, key):\n        return self.items.get(key)"
+	result, ok, strategy := MatchAndReplaceInFile("store.py", src, stale, updated)
+	if !ok || strategy != "symbol" {
+		t.Fatalf("expected a symbol match, got %v %q", ok, strategy)
+	}
+	if !strings.Contains(result, "return self.items.get(key)\n\n    def put") {
+		t.Errorf("result:\n%s", result)
+	}
+}
+
+func TestSymbolHint(t *testing.T) {
+	hint := SymbolHint("calc.go", symbolSource, "\treturn Sub(a, b) * 2")
+	if !strings.Contains(hint, "function Sub (lines 8-10)") || !strings.Contains(hint, "return a - b") {
+		t.Errorf("hint = %q", hint)
+	}
+	if hint := SymbolHint("calc.go", symbolSource, "nothing here"); hint != "" {
+		t.Errorf("expected no hint, got %q", hint)
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+
+	"github.com/jordanhubbard/loom/internal/codechunk"
+	"github.com/jordanhubbard/loom/internal/staticanalysis"
 )
 
 const (
-	maxFileContentLen = 8000
-	maxBuildOutputLen = 4000
-	maxCommandOutput  = 6000
+	maxFileContentLen     = 8000
+	maxOmittedDefinitions = 20 // Definitions named after a truncated file
+	maxBuildOutputLen     = 4000
+	maxCommandOutput      = 6000
 )
 
 // FormatResultsAsUserMessage converts action execution results into a user message
@@ -58,6 +62,8 @@ func formatSingleResult(r Result) string {
 		formatTestResult(&sb, r)
 	case ActionRunLinter:
 		formatLintResult(&sb, r)
+	case ActionStaticAnalysis:
+		formatStaticAnalysisResult(&sb, r)
 	case ActionSearchText:
 		formatSearchResult(&sb, r)
 	case ActionReadTree:
@@ -93,7 +99,7 @@ func formatFileRead(sb *strings.Builder, r Result) {
 	sb.WriteString(fmt.Sprintf("**File:** `%s` (%d bytes)\n", path, int(size)))
 
 	if len(content) > maxFileContentLen {
-		content = content[:maxFileContentLen] + "\n... (truncated)"
+		content = truncateAtDefinitions(path, content, maxFileContentLen)
 	}
 	sb.WriteString("```\n")
 	sb.WriteString(content)
@@ -103,6 +109,39 @@ func formatFileRead(sb *strings.Builder, r Result) {
 	sb.WriteString("```\n")
 }
 
+// truncateAtDefinitions cuts a file after the last whole chunk that fits
+// in limit, so the agent never sees (and copies into an edit) half a
+// function, and names the definitions left out
+func truncateAtDefinitions(path, content string, limit int) string {
+	chunks := codechunk.Split(path, content)
+	lines := strings.Split(content, "\n")
+	kept, size := 0, 0
+	for _, c := range chunks {
+		end := len(strings.Join(lines[:c.EndLine], "\n"))
+		if end > limit {
+			break
+		}
+		kept, size = c.EndLine, end
+	}
+	if kept == 0 {
+		return content[:limit] + "\n... (truncated)"
+	}
+	var omitted []string
+	for _, c := range chunks {
+		if c.StartLine > kept && c.Kind != codechunk.KindBlock {
+			omitted = append(omitted, fmt.Sprintf("%s (lines %d-%d)", c.Name, c.StartLine, c.EndLine))
+		}
+	}
+	marker := fmt.Sprintf("\n... (truncated after line %d of %d)", kept, len(lines))
+	if len(omitted) > 0 {
+		if len(omitted) > maxOmittedDefinitions {
+			omitted = append(omitted[:maxOmittedDefinitions], fmt.Sprintf("and %d more", len(omitted)-maxOmittedDefinitions))
+		}
+		marker += "\nNot shown: " + strings.Join(omitted, ", ")
+	}
+	return content[:size] + marker
+}
+
 func formatFileWrite(sb *strings.Builder, r Result) {
 	path, _ := r.Metadata["path"].(string)
 	bytesWritten, _ := r.Metadata["bytes_written"].(float64)
@@ -198,6 +237,37 @@ func formatLintResult(sb *strings.Builder, r Result) {
 	}
 }
 
+func formatStaticAnalysisResult(sb *strings.Builder, r Result) {
+	sb.WriteString(r.Message + "\n")
+	if runs, ok := r.Metadata["tools"].([]staticanalysis.ToolRun); ok {
+		for _, run := range runs {
+			if run.Error != "" {
+				sb.WriteString(fmt.Sprintf("%s did not complete: %s\n", run.Tool, run.Error))
+			}
+		}
+	}
+	findings, ok := r.Metadata["findings"].([]staticanalysis.Finding)
+	if !ok || len(findings) == 0 {
+		return
+	}
+
+	b, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		sb.WriteString(fmt.Sprintf("Findings: %v\n", findings))
+		return
+	}
+	output := string(b)
+	if len(output) > maxFileContentLen {
+		output = output[:maxFileContentLen] + "\n... (truncated)"
+	}
+	sb.WriteString("```json\n")
+	sb.WriteString(output)
+	sb.WriteString("\n```\n")
+	if n, _ := r.Metadata["truncated"].(int); n > 0 {
+		sb.WriteString(fmt.Sprintf("%d less severe findings left out.\n", n))
+	}
+}
+
 func formatSearchResult(sb *strings.Builder, r Result) {
 	matches := r.Metadata["matches"]
 	if matches == nil {
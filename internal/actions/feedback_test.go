@@ -624,3 +624,27 @@ func TestFormatResultsWithContext_NoRoot(t *testing.T) {
 		t.Error("should not contain working directory when empty")
 	}
 }
+
+func TestFormatFileRead_TruncatesAtDefinitions(t *testing.T) {
+	body := strings.Repeat("\tx++\n", 1000)
+	content := "package foo\n\nfunc first() {\n" + body + "}\n\nfunc second() {\n" + body + "}\n\nfunc third() {\n\tx++\n}\n"
+	r := Result{
+		ActionType: ActionReadFile,
+		Status:     "executed",
+		Metadata: map[string]interface{}{
+			"path":    "foo.go",
+			"content": content,
+			"size":    float64(len(content)),
+		},
+	}
+	output := formatSingleResult(r)
+	if !strings.Contains(output, "func first() {") || strings.Contains(output, "func second() {") {
+		t.Fatalf("expected only the first function, got %d bytes", len(output))
+	}
+	if !strings.Contains(output, "\tx++\n}\n... (truncated after line 1004 of 2012)") {
+		t.Error("expected the cut after the closing brace of first")
+	}
+	if !strings.Contains(output, "Not shown: second (lines 1006-2007), third (lines 2009-2011)") {
+		t.Error("expected the omitted definitions to be named")
+	}
+}
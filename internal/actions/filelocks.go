@@ -0,0 +1,48 @@
+package actions
+
+import (
+	"context"
+	"path"
+)
+
+// lockFiles locks the files an action changes for its agent, if a locker
+// is configured
+func (r *Router) lockFiles(ctx context.Context, action Action, actx ActionContext) error {
+	if r.Locks == nil {
+		return nil
+	}
+	paths := lockPaths(action)
+	if len(paths) == 0 {
+		return nil
+	}
+	return r.Locks.LockFiles(ctx, actx, paths)
+}
+
+// lockPaths returns the files an action changes, or nil for actions that
+// change none. A patch that does not parse locks nothing; it fails before
+// changing anything.
+func lockPaths(action Action) []string {
+	var paths []string
+	switch action.Type {
+	case ActionWriteFile, ActionEditCode, ActionApplyPatch, ActionDeleteFile, ActionMoveFile:
+		paths, _ = editPaths(action)
+	case ActionEditTransaction:
+		for _, edit := range action.Edits {
+			touched, _ := editPaths(edit)
+			paths = appendUnique(paths, touched...)
+		}
+	case ActionRenameFile:
+		if action.SourcePath != "" && action.NewName != "" {
+			paths = []string{action.SourcePath, path.Join(path.Dir(action.SourcePath), action.NewName)}
+		}
+	case ActionRenameSymbol:
+		paths = []string{action.Path}
+	}
+	var out []string
+	for _, p := range paths {
+		if p != "" {
+			out = appendUnique(out, p)
+		}
+	}
+	return out
+}
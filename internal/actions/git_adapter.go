@@ -26,6 +26,16 @@ func NewGitServiceAdapter(projectPath, projectID string, projectKeyDir ...string
 	}, nil
 }
 
+// SetSecretsGate configures the secrets scanner override hook for the adapter's project.
+func (a *GitServiceAdapter) SetSecretsGate(gate git.SecretsGate) {
+	a.service.SetSecretsGate(gate)
+}
+
+// SetCommitGate configures the commit approval hook for the adapter's project.
+func (a *GitServiceAdapter) SetCommitGate(gate git.CommitGate) {
+	a.service.SetCommitGate(gate)
+}
+
 // --- Existing operations ---
 
 // Status returns git status for a project (delegates to adapter's project)
@@ -263,3 +273,24 @@ func (a *GitServiceAdapter) GetBeadCommits(ctx context.Context, beadID string) (
 		"bead_id": beadID,
 	}, nil
 }
+
+// UndoBead closes a bead's PRs, deletes its branches and reverts its commits
+func (a *GitServiceAdapter) UndoBead(ctx context.Context, beadID string, branches []string, prNumbers []int, base, reason string) (map[string]interface{}, error) {
+	result, err := a.service.UndoBead(ctx, git.UndoBeadRequest{
+		BeadID:    beadID,
+		Branches:  branches,
+		PRNumbers: prNumbers,
+		Base:      base,
+		Reason:    reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"branch":           result.Branch,
+		"closed_prs":       result.ClosedPRs,
+		"deleted_branches": result.DeletedBranches,
+		"reverted_commits": result.RevertedCommits,
+		"pushed":           result.Pushed,
+	}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/jordanhubbard/loom/internal/git"
 	"github.com/jordanhubbard/loom/internal/gitops"
 )
 
@@ -33,6 +34,8 @@ type ProjectGitRouter struct {
 	gitopsMgr *gitops.Manager
 	mu        sync.RWMutex
 	cache     map[string]*GitServiceAdapter // projectID -> adapter
+	secrets   git.SecretsGate
+	commits   git.CommitGate
 }
 
 // NewProjectGitRouter creates a project-aware GitOperator.
@@ -43,6 +46,28 @@ func NewProjectGitRouter(gitopsMgr *gitops.Manager) *ProjectGitRouter {
 	}
 }
 
+// SetSecretsGate configures the secrets scanner override hook for every
+// project adapter, including ones already created.
+func (r *ProjectGitRouter) SetSecretsGate(gate git.SecretsGate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.secrets = gate
+	for _, adapter := range r.cache {
+		adapter.SetSecretsGate(gate)
+	}
+}
+
+// SetCommitGate configures the commit approval hook for every project
+// adapter, including ones already created.
+func (r *ProjectGitRouter) SetCommitGate(gate git.CommitGate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.commits = gate
+	for _, adapter := range r.cache {
+		adapter.SetCommitGate(gate)
+	}
+}
+
 // forProject returns a cached or newly-created GitServiceAdapter for the project.
 func (r *ProjectGitRouter) forProject(projectID string) (*GitServiceAdapter, error) {
 	if projectID == "" {
@@ -65,6 +90,12 @@ func (r *ProjectGitRouter) forProject(projectID string) (*GitServiceAdapter, err
 	}
 
 	r.mu.Lock()
+	if r.secrets != nil {
+		adapter.SetSecretsGate(r.secrets)
+	}
+	if r.commits != nil {
+		adapter.SetCommitGate(r.commits)
+	}
 	r.cache[projectID] = adapter
 	r.mu.Unlock()
 
@@ -210,6 +241,14 @@ func (r *ProjectGitRouter) GetBeadCommits(ctx context.Context, beadID string) (m
 	return adapter.GetBeadCommits(ctx, beadID)
 }
 
+func (r *ProjectGitRouter) UndoBead(ctx context.Context, beadID string, branches []string, prNumbers []int, base, reason string) (map[string]interface{}, error) {
+	adapter, err := r.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return adapter.UndoBead(ctx, beadID, branches, prNumbers, base, reason)
+}
+
 // ForProject returns a project-scoped GitOperator.
 func (r *ProjectGitRouter) ForProject(projectID string) (GitOperator, error) {
 	return r.forProject(projectID)
@@ -11,11 +11,15 @@ type LSPOperator interface {
 	FindReferences(ctx context.Context, file string, line, column int, symbol string) (map[string]interface{}, error)
 	GoToDefinition(ctx context.Context, file string, line, column int, symbol string) (map[string]interface{}, error)
 	FindImplementations(ctx context.Context, file string, line, column int, symbol string) (map[string]interface{}, error)
+	RenameSymbol(ctx context.Context, file string, line, column int, symbol, newName string) (map[string]interface{}, error)
 }
 
 // LSPServiceAdapter adapts LSPService to actions interface
 type LSPServiceAdapter struct {
 	service *lsp.LSPService
+	// resolve returns the service of the project in the request context,
+	// when the adapter serves several projects
+	resolve func(ctx context.Context) (*lsp.LSPService, error)
 }
 
 // NewLSPServiceAdapter creates a new adapter
@@ -30,6 +34,19 @@ func NewLSPServiceAdapter(projectPath string) (*LSPServiceAdapter, error) {
 	}, nil
 }
 
+// NewProjectLSPAdapter creates an adapter that sends each request to the
+// language servers of the project in its context, as returned by resolve
+func NewProjectLSPAdapter(resolve func(ctx context.Context) (*lsp.LSPService, error)) *LSPServiceAdapter {
+	return &LSPServiceAdapter{resolve: resolve}
+}
+
+func (a *LSPServiceAdapter) serviceFor(ctx context.Context) (*lsp.LSPService, error) {
+	if a.resolve != nil {
+		return a.resolve(ctx)
+	}
+	return a.service, nil
+}
+
 // FindReferences finds all references to a symbol
 func (a *LSPServiceAdapter) FindReferences(ctx context.Context, file string, line, column int, symbol string) (map[string]interface{}, error) {
 	req := lsp.FindReferencesRequest{
@@ -39,7 +56,11 @@ func (a *LSPServiceAdapter) FindReferences(ctx context.Context, file string, lin
 		Symbol: symbol,
 	}
 
-	locations, err := a.service.FindReferences(ctx, req)
+	service, err := a.serviceFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := service.FindReferences(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +91,11 @@ func (a *LSPServiceAdapter) GoToDefinition(ctx context.Context, file string, lin
 		Symbol: symbol,
 	}
 
-	location, err := a.service.GoToDefinition(ctx, req)
+	service, err := a.serviceFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	location, err := service.GoToDefinition(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -99,7 +124,11 @@ func (a *LSPServiceAdapter) FindImplementations(ctx context.Context, file string
 		Symbol: symbol,
 	}
 
-	locations, err := a.service.FindImplementations(ctx, req)
+	service, err := a.serviceFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	locations, err := service.FindImplementations(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -121,7 +150,35 @@ func (a *LSPServiceAdapter) FindImplementations(ctx context.Context, file string
 	}, nil
 }
 
+// RenameSymbol renames a symbol across the project and writes the edits
+func (a *LSPServiceAdapter) RenameSymbol(ctx context.Context, file string, line, column int, symbol, newName string) (map[string]interface{}, error) {
+	req := lsp.RenameRequest{
+		File:    file,
+		Line:    line,
+		Column:  column,
+		Symbol:  symbol,
+		NewName: newName,
+	}
+
+	service, err := a.serviceFor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := service.Rename(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"files": result.Files,
+		"edits": result.Edits,
+	}, nil
+}
+
 // Close closes the LSP service
 func (a *LSPServiceAdapter) Close() error {
+	if a.service == nil {
+		return nil
+	}
 	return a.service.Close()
 }
@@ -21,7 +21,8 @@ The response must be a single JSON object with this shape:
 ### File Operations
 - read_file / read_code: Read file contents. Required: path
 - write_file: Write entire file contents. Required: path, content (PREFERRED for code changes)
-- edit_code / apply_patch: Apply unified diff patch. Required: path, patch (unified diff format)
+- edit_code / apply_patch: Apply unified diff patch. Required: path, patch (unified diff, or SEARCH/REPLACE blocks: the file path, then <<<<<<< SEARCH, old lines, =======, new lines, >>>>>>> REPLACE)
+- edit_transaction: Apply several file edits as one unit. Required: edits (write_file, edit_code, apply_patch, delete_file or move_file actions). Optional: verify (commands that must pass), commit_message. Any failure rolls back every edit
 - read_tree: List directory structure. Required: path. Optional: max_depth, limit
 - search_text: Search for text/regex in files. Required: query. Optional: path, limit
 - move_file: Move/rename file. Required: source_path, target_path
@@ -58,10 +59,12 @@ The response must be a single JSON object with this shape:
 - find_references: Find all references. Required: path + (symbol or line+column)
 - go_to_definition: Go to symbol definition. Required: path + (symbol or line+column)
 - find_implementations: Find implementations. Required: path + (symbol or line+column)
+- rename_symbol: Rename a symbol everywhere it is used, via the language server. Required: path, symbol, new_name. Optional: line (or line+column) to pick one occurrence
 
 ### Agent Communication
 - send_agent_message: Send message to another agent. Required: to_agent_id or to_agent_role, message_type
 - delegate_task: Delegate work to another agent. Required: delegate_to_role, task_title
+- handoff: Hand the current bead to another persona mid-task (e.g. coder → reviewer) and stop. Required: handoff object with to_persona, summary. Optional: handoff.model_tier (small, medium, large, xlarge), handoff.open_questions, handoff.relevant_files
 
 ## Code Change Workflow
 
@@ -0,0 +1,83 @@
+package actions
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// simpleActionNames are the actions ParseSimpleJSON accepts
+var simpleActionNames = []string{
+	"scope", "tree", "read", "search", "edit", "write", "transaction", "build", "test", "analyze",
+	"bash", "git_commit", "git_push", "git_status", "done", "close_bead", "escalate", "handoff",
+}
+
+// Response schema names, as passed to providers
+const (
+	SimpleResponseSchemaName   = "loom_action"
+	EnvelopeResponseSchemaName = "loom_actions"
+)
+
+var (
+	simpleResponseSchema   = mustMarshal(simpleSchema(true))
+	envelopeResponseSchema = mustMarshal(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"actions": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"type": map[string]interface{}{"type": "string"}},
+					"required":   []string{"type"},
+				},
+			},
+			"notes": map[string]interface{}{"type": "string"},
+		},
+		"required": []string{"actions"},
+	})
+)
+
+// ResponseSchema returns the name and JSON schema of the responses the
+// action loop accepts: one simple action in text mode, or an action
+// envelope. Providers that support structured outputs are held to it.
+func ResponseSchema(textMode bool) (string, json.RawMessage) {
+	if textMode {
+		return SimpleResponseSchemaName, simpleResponseSchema
+	}
+	return EnvelopeResponseSchemaName, envelopeResponseSchema
+}
+
+// simpleSchema derives the schema of SimpleJSONAction from its fields.
+// Transaction edits use the same fields but are not nested further.
+func simpleSchema(top bool) map[string]interface{} {
+	properties := make(map[string]interface{})
+	t := reflect.TypeOf(SimpleJSONAction{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		switch {
+		case name == "action":
+			properties[name] = map[string]interface{}{"type": "string", "enum": simpleActionNames}
+		case field.Type.Kind() == reflect.String:
+			properties[name] = map[string]interface{}{"type": "string"}
+		case field.Type.Elem().Kind() == reflect.String:
+			properties[name] = map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}
+		case top:
+			properties[name] = map[string]interface{}{"type": "array", "items": simpleSchema(false)}
+		}
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             []string{"action"},
+		"additionalProperties": false,
+	}
+}
+
+func mustMarshal(v interface{}) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
@@ -0,0 +1,42 @@
+package actions
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/jsonschema"
+)
+
+func TestResponseSchema_MatchesParsers(t *testing.T) {
+	for _, name := range simpleActionNames {
+		if _, err := ParseSimpleJSON([]byte(`{"action": "` + name + `"}`)); err != nil && strings.Contains(err.Error(), "unknown action") {
+			t.Errorf("schema allows %q but the parser does not know it", name)
+		}
+	}
+
+	_, raw := ResponseSchema(true)
+	schema, err := jsonschema.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := `{"action": "transaction", "message": "m", "verify": ["go test ./..."],
+		"edits": [{"action": "edit", "path": "a.go", "old": "x", "new": "y"}]}`
+	if problems := schema.Validate([]byte(valid)); len(problems) > 0 {
+		t.Errorf("a valid transaction fails the schema: %v", problems)
+	}
+	if problems := schema.Validate([]byte(`{"action": "read", "file": "a.go"}`)); len(problems) != 1 || problems[0] != "$.file: unexpected property" {
+		t.Errorf("expected the misnamed field to be reported, got %v", problems)
+	}
+
+	_, raw = ResponseSchema(false)
+	schema, err = jsonschema.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems := schema.Validate([]byte(`{"actions": [{"type": "read_file", "path": "a.go"}]}`)); len(problems) > 0 {
+		t.Errorf("a valid envelope fails the schema: %v", problems)
+	}
+	if problems := schema.Validate([]byte(`{"actions": [{"path": "a.go"}]}`)); len(problems) != 1 {
+		t.Errorf("expected the action without a type to be reported, got %v", problems)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/jordanhubbard/loom/internal/executor"
 	"github.com/jordanhubbard/loom/internal/files"
+	"github.com/jordanhubbard/loom/internal/staticanalysis"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
@@ -23,6 +24,12 @@ type BeadEscalator interface {
 	EscalateBeadToCEO(beadID, reason, returnedTo string) (*models.DecisionBead, error)
 }
 
+// BeadHandoffer hands a bead to another persona mid-task, leaving the
+// handoff document for the agent that picks it up
+type BeadHandoffer interface {
+	HandoffBead(ctx context.Context, beadID, fromAgentID string, handoff HandoffPayload) error
+}
+
 type CommandExecutor interface {
 	ExecuteCommand(ctx context.Context, req executor.ExecuteCommandRequest) (*executor.ExecuteCommandResult, error)
 }
@@ -35,6 +42,13 @@ type LinterRunner interface {
 	Run(ctx context.Context, projectPath string, files []string, framework string, timeoutSeconds int) (map[string]interface{}, error)
 }
 
+// StaticAnalyzer runs static analysis tools over a bead's project in its
+// sandbox and parses their findings. Empty tools runs those that apply to
+// the project; empty files analyzes the whole project.
+type StaticAnalyzer interface {
+	Analyze(ctx context.Context, actx ActionContext, tools, files []string, timeoutSeconds int) (*staticanalysis.Report, error)
+}
+
 type BuildRunner interface {
 	Run(ctx context.Context, projectPath, buildTarget, buildCommand, framework string, timeoutSeconds int) (map[string]interface{}, error)
 }
@@ -69,6 +83,7 @@ type GitOperator interface {
 	ListBranches(ctx context.Context) (map[string]interface{}, error)
 	DiffBranches(ctx context.Context, branch1, branch2 string) (map[string]interface{}, error)
 	GetBeadCommits(ctx context.Context, beadID string) (map[string]interface{}, error)
+	UndoBead(ctx context.Context, beadID string, branches []string, prNumbers []int, base, reason string) (map[string]interface{}, error)
 }
 
 type ActionLogger interface {
@@ -89,6 +104,61 @@ type MessageSender interface {
 	FindAgentByRole(ctx context.Context, role string) (string, error)
 }
 
+// PullRequestHost serves pull request actions for projects whose pull
+// requests are not on GitHub, such as demo projects. Projects it does not
+// handle go through Git and the gh CLI as usual.
+type PullRequestHost interface {
+	HandlesProject(projectID string) bool
+	CreatePR(ctx context.Context, projectID, beadID, title, body, base, branch string) (map[string]interface{}, error)
+	FetchPR(ctx context.Context, projectID string, number int, includeDiff bool) (map[string]interface{}, error)
+	SubmitReview(ctx context.Context, projectID string, number int, event, body, reviewerID string) (map[string]interface{}, error)
+}
+
+// PullRequestReviewer is told about every pull request an agent opens, so
+// it can be reviewed automatically before human review. pr is the
+// create_pr result: pr_number, pr_url, branch and base.
+type PullRequestReviewer interface {
+	PullRequestOpened(ctx context.Context, actx ActionContext, title string, pr map[string]interface{})
+}
+
+// PullRequestCoster summarizes what a bead has cost so far as trailers
+// appended to the body of the pull requests its agents open. An empty
+// summary leaves the body as it is.
+type PullRequestCoster interface {
+	PullRequestCost(ctx context.Context, actx ActionContext) string
+}
+
+// PullRequestNoter returns sections a bead asks to be added to the body of
+// the pull requests its agents open, such as the changelogs of the
+// dependencies a dependency update bead bumps. An empty result leaves the
+// body as it is.
+type PullRequestNoter interface {
+	PullRequestNotes(ctx context.Context, actx ActionContext) string
+}
+
+// CIGate follows CI on the branches agents push and holds back pull
+// requests and merges of a branch until its required checks pass. An empty
+// branch is the bead's most recently pushed one.
+type CIGate interface {
+	BranchPushed(ctx context.Context, actx ActionContext, branch string)
+	CheckBranch(ctx context.Context, actx ActionContext, branch string) error
+}
+
+// ActionPolicy applies the arbiter's tool-permission and approval policies
+// to agent actions. A refused action is not run; the reason and details
+// are reported back to the agent, e.g. the decision awaiting approval.
+type ActionPolicy interface {
+	CheckAction(ctx context.Context, action Action, actx ActionContext) (allowed bool, reason string, details map[string]interface{})
+}
+
+// FileLocker keeps agents from changing the same files at once: the files
+// an action changes are locked for its agent before it runs, and held
+// until the agent's task ends. A refused lock is reported to the agent.
+type FileLocker interface {
+	LockFiles(ctx context.Context, actx ActionContext, paths []string) error
+	ReleaseFiles(ctx context.Context, actx ActionContext)
+}
+
 type ActionContext struct {
 	AgentID   string
 	BeadID    string
@@ -106,9 +176,11 @@ type Router struct {
 	Beads        BeadCreator
 	Closer       BeadCloser
 	Escalator    BeadEscalator
+	Handoffs     BeadHandoffer
 	Commands     CommandExecutor
 	Tests        TestRunner
 	Linter       LinterRunner
+	Analyzer     StaticAnalyzer
 	Builder      BuildRunner
 	Files        FileManager
 	Git          GitOperator
@@ -116,6 +188,13 @@ type Router struct {
 	Workflow     WorkflowOperator
 	LSP          LSPOperator
 	MessageBus   MessageSender
+	PullRequests PullRequestHost
+	Reviewer     PullRequestReviewer
+	Costs        PullRequestCoster
+	Notes        PullRequestNoter
+	CI           CIGate
+	Policy       ActionPolicy
+	Locks        FileLocker
 	BeadType     string
 	BeadTags     []string
 	DefaultP0 bool
@@ -133,7 +212,14 @@ func (r *Router) Execute(ctx context.Context, env *ActionEnvelope, actx ActionCo
 
 	results := make([]Result, 0, len(env.Actions))
 	for _, action := range env.Actions {
-		result := r.executeAction(ctx, action, actx)
+		var result Result
+		if allowed, reason, details := r.checkPolicy(ctx, action, actx); !allowed {
+			result = Result{ActionType: action.Type, Status: "error", Message: reason, Metadata: details}
+		} else if err := r.lockFiles(ctx, action, actx); err != nil {
+			result = Result{ActionType: action.Type, Status: "error", Message: err.Error()}
+		} else {
+			result = r.executeAction(ctx, action, actx)
+		}
 		if r.Logger != nil {
 			r.Logger.LogAction(ctx, actx, action, result)
 		}
@@ -143,6 +229,14 @@ func (r *Router) Execute(ctx context.Context, env *ActionEnvelope, actx ActionCo
 	return results, nil
 }
 
+// checkPolicy asks the action policy, if any, whether the action may run
+func (r *Router) checkPolicy(ctx context.Context, action Action, actx ActionContext) (bool, string, map[string]interface{}) {
+	if r.Policy == nil {
+		return true, "", nil
+	}
+	return r.Policy.CheckAction(ctx, action, actx)
+}
+
 func (r *Router) AutoFileParseFailure(ctx context.Context, actx ActionContext, err error, raw string) Result {
 	if r.Beads == nil {
 		return Result{ActionType: ActionCreateBead, Status: "error", Message: "bead creator not configured"}
@@ -200,10 +294,13 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 			if readErr != nil {
 				return Result{ActionType: action.Type, Status: "error", Message: fmt.Sprintf("cannot read %s: %v", action.Path, readErr)}
 			}
-			newContent, matched, strategy := MatchAndReplace(res.Content, action.OldText, action.NewText)
+			newContent, matched, strategy := MatchAndReplaceInFile(action.Path, res.Content, action.OldText, action.NewText)
 			if !matched {
-				return Result{ActionType: action.Type, Status: "error",
-					Message: fmt.Sprintf("OLD text not found in %s (tried exact, line-trimmed, whitespace-normalized, indentation-flexible, block-anchor matching). Re-read the file with ACTION: READ and copy the exact text.", action.Path)}
+				message := fmt.Sprintf("OLD text not found in %s (tried exact, line-trimmed, whitespace-normalized, indentation-flexible, block-anchor and whole-symbol matching). Re-read the file with ACTION: READ and copy the exact text.", action.Path)
+				if hint := SymbolHint(action.Path, res.Content, action.OldText); hint != "" {
+					message += "\n\n" + hint
+				}
+				return Result{ActionType: action.Type, Status: "error", Message: message}
 			}
 			writeRes, writeErr := r.Files.WriteFile(ctx, actx.ProjectID, action.Path, newContent)
 			if writeErr != nil {
@@ -326,6 +423,8 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 			Message:    "patch applied",
 			Metadata:   map[string]interface{}{"output": res.Output},
 		}
+	case ActionEditTransaction:
+		return r.executeTransaction(ctx, action, actx)
 	case ActionGitStatus:
 		if r.Git == nil {
 			return Result{ActionType: action.Type, Status: "error", Message: "git operator not configured"}
@@ -387,6 +486,10 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 		if err != nil {
 			return Result{ActionType: action.Type, Status: "error", Message: err.Error()}
 		}
+		if r.CI != nil {
+			branch, _ := result["branch"].(string)
+			r.CI.BranchPushed(ctx, actx, branch)
+		}
 
 		return Result{
 			ActionType: action.Type,
@@ -395,7 +498,8 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 			Metadata:   result,
 		}
 	case ActionCreatePR:
-		if r.Git == nil {
+		host := r.pullRequestHost(actx.ProjectID)
+		if r.Git == nil && host == nil {
 			return Result{ActionType: action.Type, Status: "error", Message: "git operator not configured"}
 		}
 
@@ -408,6 +512,16 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 		if body == "" {
 			body = fmt.Sprintf("Automated pull request from bead %s\n\nAgent: %s", actx.BeadID, actx.AgentID)
 		}
+		if r.Notes != nil {
+			if notes := r.Notes.PullRequestNotes(ctx, actx); notes != "" && !strings.Contains(body, notes) {
+				body = strings.TrimRight(body, "\n") + "\n\n" + notes
+			}
+		}
+		if r.Costs != nil {
+			if summary := r.Costs.PullRequestCost(ctx, actx); summary != "" {
+				body = strings.TrimRight(body, "\n") + "\n\nBead: " + actx.BeadID + "\n" + summary
+			}
+		}
 
 		// Set default base branch
 		base := action.PRBase
@@ -415,10 +529,25 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 			base = "main"
 		}
 
-		result, err := r.Git.CreatePR(ctx, actx.BeadID, title, body, base, action.Branch, action.PRReviewers, false)
+		if r.CI != nil {
+			if err := r.CI.CheckBranch(ctx, actx, action.Branch); err != nil {
+				return Result{ActionType: action.Type, Status: "error", Message: err.Error()}
+			}
+		}
+
+		var result map[string]interface{}
+		var err error
+		if host != nil {
+			result, err = host.CreatePR(ctx, actx.ProjectID, actx.BeadID, title, body, base, action.Branch)
+		} else {
+			result, err = r.Git.CreatePR(ctx, actx.BeadID, title, body, base, action.Branch, action.PRReviewers, false)
+		}
 		if err != nil {
 			return Result{ActionType: action.Type, Status: "error", Message: err.Error()}
 		}
+		if r.Reviewer != nil {
+			r.Reviewer.PullRequestOpened(ctx, actx, title, result)
+		}
 
 		return Result{
 			ActionType: action.Type,
@@ -431,6 +560,11 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 		if r.Git == nil {
 			return Result{ActionType: action.Type, Status: "error", Message: "git operator not configured"}
 		}
+		if r.CI != nil {
+			if err := r.CI.CheckBranch(ctx, actx, action.SourceBranch); err != nil {
+				return Result{ActionType: action.Type, Status: "error", Message: err.Error()}
+			}
+		}
 		noFF := action.NoFF
 		if !noFF {
 			noFF = true // Default to --no-ff for audit trail
@@ -593,6 +727,25 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 			Message:    "linter executed",
 			Metadata:   result,
 		}
+	case ActionStaticAnalysis:
+		if r.Analyzer == nil {
+			return Result{ActionType: action.Type, Status: "error", Message: "static analysis not configured"}
+		}
+		report, err := r.Analyzer.Analyze(ctx, actx, action.Tools, action.Files, action.TimeoutSeconds)
+		if err != nil {
+			return Result{ActionType: action.Type, Status: "error", Message: err.Error()}
+		}
+		return Result{
+			ActionType: action.Type,
+			Status:     "executed",
+			Message:    report.Summary(),
+			Metadata: map[string]interface{}{
+				"tools":     report.Tools,
+				"findings":  report.Findings,
+				"counts":    report.Counts,
+				"truncated": report.Truncated,
+			},
+		}
 	case ActionBuildProject:
 		if r.Builder == nil {
 			return Result{ActionType: action.Type, Status: "error", Message: "builder not configured"}
@@ -822,16 +975,24 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 			},
 		}
 	case ActionRenameSymbol:
-		// Rename symbol refactoring
+		// Rename symbol refactoring, applied by the language server
+		if r.LSP == nil {
+			return Result{ActionType: action.Type, Status: "error", Message: "LSP operator not configured"}
+		}
+
+		result, err := r.LSP.RenameSymbol(ctx, action.Path, action.Line, action.Column, action.Symbol, action.NewName)
+		if err != nil {
+			return Result{ActionType: action.Type, Status: "error", Message: err.Error()}
+		}
+		result["old_name"] = action.Symbol
+		result["new_name"] = action.NewName
+		result["file"] = action.Path
+
 		return Result{
 			ActionType: action.Type,
 			Status:     "executed",
-			Message:    fmt.Sprintf("Renamed %s to %s", action.Symbol, action.NewName),
-			Metadata: map[string]interface{}{
-				"old_name": action.Symbol,
-				"new_name": action.NewName,
-				"file":     action.Path,
-			},
+			Message:    fmt.Sprintf("Renamed %s to %s (%v edits)", action.Symbol, action.NewName, result["edits"]),
+			Metadata:   result,
 		}
 	case ActionInlineVariable:
 		// Inline variable refactoring
@@ -956,12 +1117,23 @@ func (r *Router) executeAction(ctx context.Context, action Action, actx ActionCo
 		return r.handleSendAgentMessage(ctx, action, actx)
 	case ActionDelegateTask:
 		return r.handleDelegateTask(ctx, action, actx)
+	case ActionHandoff:
+		return r.handleHandoff(ctx, action, actx)
 
 	default:
 		return Result{ActionType: action.Type, Status: "error", Message: "unsupported action"}
 	}
 }
 
+// pullRequestHost returns the host serving the project's pull requests, or
+// nil when they go through GitHub
+func (r *Router) pullRequestHost(projectID string) PullRequestHost {
+	if r.PullRequests == nil || !r.PullRequests.HandlesProject(projectID) {
+		return nil
+	}
+	return r.PullRequests
+}
+
 func (r *Router) createBeadFromAction(title, detail string, actx ActionContext) Result {
 	if r.Beads == nil {
 		return Result{ActionType: ActionCreateBead, Status: "error", Message: "bead creator not configured"}
@@ -1000,6 +1172,19 @@ func (r *Router) handleFetchPR(ctx context.Context, action Action, actx ActionCo
 		return Result{ActionType: action.Type, Status: "error", Message: "pr_number is required"}
 	}
 
+	if host := r.pullRequestHost(actx.ProjectID); host != nil {
+		prData, err := host.FetchPR(ctx, actx.ProjectID, action.PRNumber, action.IncludeDiff)
+		if err != nil {
+			return Result{ActionType: action.Type, Status: "error", Message: fmt.Sprintf("failed to fetch PR: %v", err)}
+		}
+		return Result{
+			ActionType: action.Type,
+			Status:     "executed",
+			Message:    fmt.Sprintf("Fetched PR #%d", action.PRNumber),
+			Metadata:   prData,
+		}
+	}
+
 	if r.Commands == nil {
 		return Result{ActionType: action.Type, Status: "error", Message: "command executor not configured"}
 	}
@@ -1163,10 +1348,6 @@ func (r *Router) handleSubmitReview(ctx context.Context, action Action, actx Act
 		return Result{ActionType: action.Type, Status: "error", Message: "comment_body is required"}
 	}
 
-	if r.Commands == nil {
-		return Result{ActionType: action.Type, Status: "error", Message: "command executor not configured"}
-	}
-
 	// Validate review event
 	validEvents := map[string]bool{
 		"APPROVE":         true,
@@ -1177,6 +1358,23 @@ func (r *Router) handleSubmitReview(ctx context.Context, action Action, actx Act
 		return Result{ActionType: action.Type, Status: "error", Message: "invalid review_event"}
 	}
 
+	if host := r.pullRequestHost(actx.ProjectID); host != nil {
+		metadata, err := host.SubmitReview(ctx, actx.ProjectID, action.PRNumber, action.ReviewEvent, action.CommentBody, actx.AgentID)
+		if err != nil {
+			return Result{ActionType: action.Type, Status: "error", Message: fmt.Sprintf("failed to submit review: %v", err)}
+		}
+		return Result{
+			ActionType: action.Type,
+			Status:     "executed",
+			Message:    fmt.Sprintf("Submitted review for PR #%d: %s", action.PRNumber, action.ReviewEvent),
+			Metadata:   metadata,
+		}
+	}
+
+	if r.Commands == nil {
+		return Result{ActionType: action.Type, Status: "error", Message: "command executor not configured"}
+	}
+
 	// Build gh CLI command
 	eventFlag := "--" + strings.ToLower(strings.ReplaceAll(action.ReviewEvent, "_", "-"))
 	cmd := fmt.Sprintf("gh pr review %d %s --body %q", action.PRNumber, eventFlag, action.CommentBody)
@@ -1368,3 +1566,30 @@ func (r *Router) handleDelegateTask(ctx context.Context, action Action, actx Act
 		},
 	}
 }
+
+func (r *Router) handleHandoff(ctx context.Context, action Action, actx ActionContext) Result {
+	if action.Handoff == nil || action.Handoff.ToPersona == "" || action.Handoff.Summary == "" {
+		return Result{ActionType: action.Type, Status: "error", Message: "handoff requires handoff.to_persona and handoff.summary"}
+	}
+	if actx.BeadID == "" {
+		return Result{ActionType: action.Type, Status: "error", Message: "handoff requires a bead in progress"}
+	}
+	if r.Handoffs == nil {
+		return Result{ActionType: action.Type, Status: "error", Message: "bead handoffs not configured"}
+	}
+
+	if err := r.Handoffs.HandoffBead(ctx, actx.BeadID, actx.AgentID, *action.Handoff); err != nil {
+		return Result{ActionType: action.Type, Status: "error", Message: fmt.Sprintf("failed to hand off bead: %v", err)}
+	}
+
+	return Result{
+		ActionType: action.Type,
+		Status:     "executed",
+		Message:    fmt.Sprintf("Handed bead %s off to %s", actx.BeadID, action.Handoff.ToPersona),
+		Metadata: map[string]interface{}{
+			"bead_id":    actx.BeadID,
+			"to_persona": action.Handoff.ToPersona,
+			"model_tier": action.Handoff.ModelTier,
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// mockCIGate fails the branches in failing and records pushed branches
+type mockCIGate struct {
+	failing map[string]bool
+	pushed  []string
+	checked []string
+}
+
+func (g *mockCIGate) BranchPushed(ctx context.Context, actx ActionContext, branch string) {
+	g.pushed = append(g.pushed, actx.BeadID+":"+branch)
+}
+
+func (g *mockCIGate) CheckBranch(ctx context.Context, actx ActionContext, branch string) error {
+	g.checked = append(g.checked, branch)
+	if g.failing[branch] {
+		return fmt.Errorf("CI failed on %s: test", branch)
+	}
+	return nil
+}
+
+func TestCIGate_TracksPushesAndHoldsBackFailingBranches(t *testing.T) {
+	gate := &mockCIGate{failing: map[string]bool{"agent/bead-1/red": true}}
+	git := &mockGitOperator{result: map[string]interface{}{"branch": "agent/bead-1/red"}}
+	r := &Router{Git: git, CI: gate}
+	actx := ActionContext{AgentID: "agent-1", BeadID: "bead-1", ProjectID: "proj-1"}
+
+	if result := r.executeAction(context.Background(), Action{Type: ActionGitPush}, actx); result.Status != "executed" {
+		t.Fatalf("git_push = %s: %s", result.Status, result.Message)
+	}
+	if len(gate.pushed) != 1 || gate.pushed[0] != "bead-1:agent/bead-1/red" {
+		t.Errorf("expected the pushed branch to be tracked, got %v", gate.pushed)
+	}
+
+	result := r.executeAction(context.Background(), Action{Type: ActionCreatePR, Branch: "agent/bead-1/red"}, actx)
+	if result.Status != "error" || result.Message != "CI failed on agent/bead-1/red: test" {
+		t.Errorf("expected create_pr to be held back, got %s: %s", result.Status, result.Message)
+	}
+	result = r.executeAction(context.Background(), Action{Type: ActionGitMerge, SourceBranch: "agent/bead-1/red"}, actx)
+	if result.Status != "error" {
+		t.Errorf("expected git_merge to be held back, got %s", result.Status)
+	}
+
+	if result := r.executeAction(context.Background(), Action{Type: ActionCreatePR, Branch: "agent/bead-1/green"}, actx); result.Status != "executed" {
+		t.Errorf("expected create_pr of a passing branch to run, got %s: %s", result.Status, result.Message)
+	}
+	if len(gate.checked) != 3 {
+		t.Errorf("expected three gate checks, got %v", gate.checked)
+	}
+}
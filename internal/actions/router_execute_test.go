@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/jordanhubbard/loom/internal/executor"
 	"github.com/jordanhubbard/loom/internal/files"
+	"github.com/jordanhubbard/loom/internal/staticanalysis"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
@@ -142,6 +144,7 @@ type mockGitOperator struct {
 	diffErr   error
 	result    map[string]interface{}
 	err       error
+	prBody    string
 }
 
 func (m *mockGitOperator) Status(ctx context.Context, projectID string) (string, error) {
@@ -166,6 +169,7 @@ func (m *mockGitOperator) GetDiff(ctx context.Context, staged bool) (map[string]
 	return m.result, m.err
 }
 func (m *mockGitOperator) CreatePR(ctx context.Context, beadID, title, body, base, branch string, reviewers []string, draft bool) (map[string]interface{}, error) {
+	m.prBody = body
 	if m.result == nil {
 		return map[string]interface{}{"pr_url": "https://github.com/test/pr/1"}, m.err
 	}
@@ -198,6 +202,9 @@ func (m *mockGitOperator) DiffBranches(ctx context.Context, branch1, branch2 str
 func (m *mockGitOperator) GetBeadCommits(ctx context.Context, beadID string) (map[string]interface{}, error) {
 	return m.result, m.err
 }
+func (m *mockGitOperator) UndoBead(ctx context.Context, beadID string, branches []string, prNumbers []int, base, reason string) (map[string]interface{}, error) {
+	return m.result, m.err
+}
 
 type mockWorkflowOperator struct {
 	advanceErr error
@@ -223,10 +230,11 @@ func (m *mockWorkflowOperator) ResumeWorkflow(ctx context.Context, includeSystem
 }
 
 type mockLSPOperator struct {
-	refResult  map[string]interface{}
-	defResult  map[string]interface{}
-	implResult map[string]interface{}
-	err        error
+	refResult    map[string]interface{}
+	defResult    map[string]interface{}
+	implResult   map[string]interface{}
+	renameResult map[string]interface{}
+	err          error
 }
 
 func (m *mockLSPOperator) FindReferences(ctx context.Context, file string, line, column int, symbol string) (map[string]interface{}, error) {
@@ -238,6 +246,9 @@ func (m *mockLSPOperator) GoToDefinition(ctx context.Context, file string, line,
 func (m *mockLSPOperator) FindImplementations(ctx context.Context, file string, line, column int, symbol string) (map[string]interface{}, error) {
 	return m.implResult, m.err
 }
+func (m *mockLSPOperator) RenameSymbol(ctx context.Context, file string, line, column int, symbol, newName string) (map[string]interface{}, error) {
+	return m.renameResult, m.err
+}
 
 type mockActionLogger struct {
 	logged []Action
@@ -613,6 +624,96 @@ func TestRouter_CreatePR_Defaults(t *testing.T) {
 	}
 }
 
+type fixedCoster string
+
+func (c fixedCoster) PullRequestCost(ctx context.Context, actx ActionContext) string {
+	return string(c)
+}
+
+func TestRouter_CreatePR_CostTrailer(t *testing.T) {
+	git := &mockGitOperator{}
+	r := &Router{Git: git, Costs: fixedCoster("Cost: $0.4200\nTokens: 1200\nIterations: 7")}
+	result := r.executeAction(context.Background(), Action{Type: ActionCreatePR, PRBody: "Desc\n", Branch: "feature"}, ActionContext{BeadID: "bead-1", AgentID: "agent-1"})
+	if result.Status != "executed" {
+		t.Fatalf("expected executed, got %s: %s", result.Status, result.Message)
+	}
+	if want := "Desc\n\nBead: bead-1\nCost: $0.4200\nTokens: 1200\nIterations: 7"; git.prBody != want {
+		t.Errorf("expected cost trailers in the body, got %q", git.prBody)
+	}
+
+	r.Costs = fixedCoster("")
+	r.executeAction(context.Background(), Action{Type: ActionCreatePR, PRBody: "Desc", Branch: "feature"}, ActionContext{BeadID: "bead-1"})
+	if git.prBody != "Desc" {
+		t.Errorf("expected the body unchanged without a cost, got %q", git.prBody)
+	}
+}
+
+type fixedNoter string
+
+func (n fixedNoter) PullRequestNotes(ctx context.Context, actx ActionContext) string {
+	return string(n)
+}
+
+func TestRouter_CreatePR_Notes(t *testing.T) {
+	git := &mockGitOperator{}
+	notes := "## Dependency changelogs\n\n### lib v1.0.0 -> v1.1.0\n- v1.1.0: Adds streaming"
+	r := &Router{Git: git, Notes: fixedNoter(notes), Costs: fixedCoster("Cost: $0.10")}
+	r.executeAction(context.Background(), Action{Type: ActionCreatePR, PRBody: "Bump lib\n", Branch: "deps"}, ActionContext{BeadID: "bead-1"})
+	if want := "Bump lib\n\n" + notes + "\n\nBead: bead-1\nCost: $0.10"; git.prBody != want {
+		t.Errorf("expected the notes before the cost trailers, got %q", git.prBody)
+	}
+
+	// Agents that already pasted the notes do not get them twice
+	r.Costs = nil
+	r.executeAction(context.Background(), Action{Type: ActionCreatePR, PRBody: "Bump lib\n\n" + notes, Branch: "deps"}, ActionContext{BeadID: "bead-1"})
+	if strings.Count(git.prBody, "Dependency changelogs") != 1 {
+		t.Errorf("expected the notes once, got %q", git.prBody)
+	}
+}
+
+type fakeAnalyzer struct {
+	tools, files []string
+	report       *staticanalysis.Report
+}
+
+func (f *fakeAnalyzer) Analyze(ctx context.Context, actx ActionContext, tools, files []string, timeoutSeconds int) (*staticanalysis.Report, error) {
+	f.tools, f.files = tools, files
+	return f.report, nil
+}
+
+func TestRouter_StaticAnalysis(t *testing.T) {
+	r := &Router{}
+	result := r.executeAction(context.Background(), Action{Type: ActionStaticAnalysis}, ActionContext{})
+	if result.Status != "error" {
+		t.Errorf("expected an error without an analyzer, got %s", result.Status)
+	}
+
+	analyzer := &fakeAnalyzer{report: &staticanalysis.Report{
+		Tools: []staticanalysis.ToolRun{
+			{Tool: staticanalysis.ToolGosec, Findings: 1},
+			{Tool: staticanalysis.ToolStaticcheck, Error: "staticcheck is not installed"},
+		},
+		Findings: []staticanalysis.Finding{{Tool: staticanalysis.ToolGosec, Rule: "G101", Severity: staticanalysis.SeverityError, File: "config.go", Line: 12, Message: "Potential hardcoded credentials"}},
+		Counts:   map[string]int{staticanalysis.SeverityError: 1},
+	}}
+	r.Analyzer = analyzer
+	action := Action{Type: ActionStaticAnalysis, Tools: []string{"gosec", "staticcheck"}, Files: []string{"config.go"}}
+	result = r.executeAction(context.Background(), action, ActionContext{BeadID: "bead-1"})
+	if result.Status != "executed" || result.Message != "gosec, staticcheck: 1 error" {
+		t.Fatalf("result = %+v", result)
+	}
+	if len(analyzer.tools) != 2 || analyzer.files[0] != "config.go" {
+		t.Errorf("analyzer got tools %v files %v", analyzer.tools, analyzer.files)
+	}
+
+	msg := FormatResultsAsUserMessage([]Result{result})
+	for _, want := range []string{"staticcheck did not complete: staticcheck is not installed", `"rule": "G101"`, `"file": "config.go"`} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("feedback missing %q:\n%s", want, msg)
+		}
+	}
+}
+
 func TestRouter_GitMerge(t *testing.T) {
 	git := &mockGitOperator{result: map[string]interface{}{"success": true}}
 	r := &Router{Git: git}
@@ -956,7 +1057,6 @@ func TestRouter_RefactoringActions(t *testing.T) {
 		action Action
 	}{
 		{"extract_method", Action{Type: ActionExtractMethod, Path: "f.go", MethodName: "doStuff", StartLine: 1, EndLine: 10}},
-		{"rename_symbol", Action{Type: ActionRenameSymbol, Path: "f.go", Symbol: "old", NewName: "new"}},
 		{"inline_variable", Action{Type: ActionInlineVariable, Path: "f.go", VariableName: "tmp"}},
 	}
 
@@ -970,6 +1070,28 @@ func TestRouter_RefactoringActions(t *testing.T) {
 	}
 }
 
+func TestRouter_RenameSymbol(t *testing.T) {
+	action := Action{Type: ActionRenameSymbol, Path: "f.go", Symbol: "old", NewName: "new"}
+	r := &Router{}
+	if result := r.executeAction(context.Background(), action, ActionContext{}); result.Status != "error" {
+		t.Errorf("expected error without a language server, got %s", result.Status)
+	}
+
+	r.LSP = &mockLSPOperator{renameResult: map[string]interface{}{"files": []string{"f.go", "g.go"}, "edits": 3}}
+	result := r.executeAction(context.Background(), action, ActionContext{})
+	if result.Status != "executed" || result.Message != "Renamed old to new (3 edits)" {
+		t.Errorf("result = %+v", result)
+	}
+	if files, _ := result.Metadata["files"].([]string); len(files) != 2 || result.Metadata["new_name"] != "new" {
+		t.Errorf("metadata = %+v", result.Metadata)
+	}
+
+	r.LSP = &mockLSPOperator{err: errors.New("no identifier found")}
+	if result := r.executeAction(context.Background(), action, ActionContext{}); result.Status != "error" {
+		t.Errorf("expected error, got %s", result.Status)
+	}
+}
+
 func TestRouter_FileManagementActions(t *testing.T) {
 	fm := &mockFileManager{}
 	r := &Router{Files: fm}
@@ -1579,3 +1701,17 @@ func TestRouter_CreateBeadFromAction_DefaultType(t *testing.T) {
 	}
 	// When BeadType is empty, default is "task"
 }
+
+func TestRouter_EditCode_TextBased_SymbolHint(t *testing.T) {
+	fm := &mockFileManager{
+		readResult: &files.FileResult{Path: "foo.go", Content: "package foo\n\nfunc run() {\n\tstart()\n}\n"},
+	}
+	r := &Router{Files: fm}
+	result := r.executeAction(context.Background(), Action{Type: ActionEditCode, Path: "foo.go", OldText: "func run() {\n\tbegin()", NewText: "x"}, ActionContext{})
+	if result.Status != "error" {
+		t.Fatalf("expected error, got %s", result.Status)
+	}
+	if !containsStr(result.Message, "Current text of function run (lines 3-5)") {
+		t.Errorf("expected the current definition in the message, got %s", result.Message)
+	}
+}
@@ -0,0 +1,60 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// mockFileLocker refuses the paths in held and records the paths locked
+type mockFileLocker struct {
+	held   map[string]bool
+	locked [][]string
+}
+
+func (l *mockFileLocker) LockFiles(ctx context.Context, actx ActionContext, paths []string) error {
+	l.locked = append(l.locked, paths)
+	for _, p := range paths {
+		if l.held[p] {
+			return fmt.Errorf("%s already locked by agent agent-2", p)
+		}
+	}
+	return nil
+}
+
+func (l *mockFileLocker) ReleaseFiles(ctx context.Context, actx ActionContext) {}
+
+func TestRouter_LocksFilesBeforeChangingThem(t *testing.T) {
+	locks := &mockFileLocker{held: map[string]bool{"busy.go": true}}
+	r := &Router{Files: &mockFileManager{}, Locks: locks}
+	actx := ActionContext{AgentID: "agent-1", BeadID: "bead-1", ProjectID: "proj-1"}
+
+	results, err := r.Execute(context.Background(), &ActionEnvelope{Actions: []Action{
+		{Type: ActionWriteFile, Path: "free.go", Content: "package x"},
+		{Type: ActionWriteFile, Path: "busy.go", Content: "package x"},
+		{Type: ActionReadFile, Path: "busy.go"},
+		{Type: ActionMoveFile, SourcePath: "a.go", TargetPath: "b/a.go"},
+		{Type: ActionEditTransaction, Edits: []Action{
+			{Type: ActionWriteFile, Path: "c.go", Content: "x"},
+			{Type: ActionDeleteFile, Path: "free.go"},
+		}},
+	}}, actx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status != "executed" {
+		t.Errorf("write to an unlocked file = %s: %s", results[0].Status, results[0].Message)
+	}
+	if results[1].Status != "error" || results[1].Message != "busy.go already locked by agent agent-2" {
+		t.Errorf("write to a locked file = %s: %s", results[1].Status, results[1].Message)
+	}
+	if results[2].Status != "executed" {
+		t.Errorf("reading a locked file = %s: %s", results[2].Status, results[2].Message)
+	}
+
+	want := [][]string{{"free.go"}, {"busy.go"}, {"a.go", "b/a.go"}, {"c.go", "free.go"}}
+	if !reflect.DeepEqual(locks.locked, want) {
+		t.Errorf("locked %v, want %v", locks.locked, want)
+	}
+}
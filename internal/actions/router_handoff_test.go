@@ -0,0 +1,74 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockBeadHandoffer struct {
+	beadID    string
+	fromAgent string
+	handoff   HandoffPayload
+	err       error
+}
+
+func (m *mockBeadHandoffer) HandoffBead(ctx context.Context, beadID, fromAgentID string, handoff HandoffPayload) error {
+	m.beadID, m.fromAgent, m.handoff = beadID, fromAgentID, handoff
+	return m.err
+}
+
+func TestHandleHandoff_Success(t *testing.T) {
+	handoffs := &mockBeadHandoffer{}
+	router := &Router{Handoffs: handoffs}
+	action := Action{Type: ActionHandoff, Handoff: &HandoffPayload{
+		ToPersona:     "code-reviewer",
+		ModelTier:     "large",
+		Summary:       "Implemented the parser, needs review",
+		OpenQuestions: []string{"Is the error wording right?"},
+		RelevantFiles: []string{"parser.go"},
+	}}
+	actx := ActionContext{AgentID: "agent-coder", BeadID: "bead-1", ProjectID: "project-1"}
+
+	result := router.executeAction(context.Background(), action, actx)
+
+	assert.Equal(t, "executed", result.Status)
+	assert.Equal(t, "code-reviewer", result.Metadata["to_persona"])
+	assert.Equal(t, "bead-1", handoffs.beadID)
+	assert.Equal(t, "agent-coder", handoffs.fromAgent)
+	require.Equal(t, []string{"parser.go"}, handoffs.handoff.RelevantFiles)
+}
+
+func TestHandleHandoff_Errors(t *testing.T) {
+	payload := &HandoffPayload{ToPersona: "code-reviewer", Summary: "done coding"}
+	tests := []struct {
+		name   string
+		router *Router
+		action Action
+		actx   ActionContext
+	}{
+		{"missing summary", &Router{Handoffs: &mockBeadHandoffer{}}, Action{Type: ActionHandoff, Handoff: &HandoffPayload{ToPersona: "code-reviewer"}}, ActionContext{BeadID: "bead-1"}},
+		{"no bead", &Router{Handoffs: &mockBeadHandoffer{}}, Action{Type: ActionHandoff, Handoff: payload}, ActionContext{}},
+		{"not configured", &Router{}, Action{Type: ActionHandoff, Handoff: payload}, ActionContext{BeadID: "bead-1"}},
+		{"refused", &Router{Handoffs: &mockBeadHandoffer{err: errors.New("unknown persona")}}, Action{Type: ActionHandoff, Handoff: payload}, ActionContext{BeadID: "bead-1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := tt.router.handleHandoff(context.Background(), tt.action, tt.actx)
+			assert.Equal(t, "error", result.Status)
+		})
+	}
+}
+
+func TestDecodeStrict_Handoff(t *testing.T) {
+	env, err := DecodeStrict([]byte(`{"actions":[{"type":"handoff","handoff":{"to_persona":"code-reviewer","summary":"ready for review","relevant_files":["a.go"]}}]}`))
+	require.NoError(t, err)
+	require.NotNil(t, env.Actions[0].Handoff)
+	assert.Equal(t, "code-reviewer", env.Actions[0].Handoff.ToPersona)
+
+	_, err = DecodeStrict([]byte(`{"actions":[{"type":"handoff","handoff":{"to_persona":"code-reviewer"}}]}`))
+	assert.Error(t, err, "a handoff without a summary should not validate")
+}
@@ -249,3 +249,87 @@ type mockCommandExecutorFunc struct {
 func (m *mockCommandExecutorFunc) ExecuteCommand(ctx context.Context, req executor.ExecuteCommandRequest) (*executor.ExecuteCommandResult, error) {
 	return m.fn(ctx, req)
 }
+
+// mockPullRequestHost serves pull requests for project "demo" only
+type mockPullRequestHost struct {
+	created  string
+	reviewed string
+}
+
+func (h *mockPullRequestHost) HandlesProject(projectID string) bool { return projectID == "demo" }
+
+func (h *mockPullRequestHost) CreatePR(ctx context.Context, projectID, beadID, title, body, base, branch string) (map[string]interface{}, error) {
+	h.created = beadID + ":" + base
+	return map[string]interface{}{"pr_number": 1, "pr_url": "demo://pull/1"}, nil
+}
+
+func (h *mockPullRequestHost) FetchPR(ctx context.Context, projectID string, number int, includeDiff bool) (map[string]interface{}, error) {
+	return map[string]interface{}{"number": number, "diff": "+fixed"}, nil
+}
+
+func (h *mockPullRequestHost) SubmitReview(ctx context.Context, projectID string, number int, event, body, reviewerID string) (map[string]interface{}, error) {
+	h.reviewed = event + ":" + reviewerID
+	return map[string]interface{}{"pr_number": number, "event": event}, nil
+}
+
+func TestPullRequestHost_ServesItsProjects(t *testing.T) {
+	host := &mockPullRequestHost{}
+	r := &Router{PullRequests: host}
+	actx := ActionContext{AgentID: "agent-1", BeadID: "bead-1", ProjectID: "demo"}
+
+	result := r.executeAction(context.Background(), Action{Type: ActionCreatePR}, actx)
+	if result.Status != "executed" || host.created != "bead-1:main" {
+		t.Fatalf("create_pr = %s %q, host saw %q", result.Status, result.Message, host.created)
+	}
+
+	result = r.handleFetchPR(context.Background(), Action{Type: ActionFetchPR, PRNumber: 1, IncludeDiff: true}, actx)
+	if result.Status != "executed" || result.Metadata["diff"] != "+fixed" {
+		t.Fatalf("fetch_pr = %s %v", result.Status, result.Metadata)
+	}
+
+	result = r.handleSubmitReview(context.Background(), Action{
+		Type: ActionSubmitReview, PRNumber: 1, ReviewEvent: "APPROVE", CommentBody: "LGTM",
+	}, actx)
+	if result.Status != "executed" || host.reviewed != "APPROVE:agent-1" {
+		t.Fatalf("submit_review = %s %q, host saw %q", result.Status, result.Message, host.reviewed)
+	}
+}
+
+func TestPullRequestHost_OtherProjectsUseGitHub(t *testing.T) {
+	host := &mockPullRequestHost{}
+	r := &Router{PullRequests: host}
+	actx := ActionContext{ProjectID: "real"}
+
+	result := r.executeAction(context.Background(), Action{Type: ActionCreatePR}, actx)
+	if result.Status != "error" || host.created != "" {
+		t.Errorf("create_pr without git = %s, host saw %q", result.Status, host.created)
+	}
+	result = r.handleFetchPR(context.Background(), Action{Type: ActionFetchPR, PRNumber: 1}, actx)
+	if result.Status != "error" || !containsStr(result.Message, "command executor") {
+		t.Errorf("fetch_pr without commands = %s %q", result.Status, result.Message)
+	}
+}
+
+// mockPullRequestReviewer records the pull requests it is told about
+type mockPullRequestReviewer struct {
+	opened []map[string]interface{}
+}
+
+func (m *mockPullRequestReviewer) PullRequestOpened(ctx context.Context, actx ActionContext, title string, pr map[string]interface{}) {
+	m.opened = append(m.opened, pr)
+}
+
+func TestPullRequestReviewer_ToldAboutOpenedPRs(t *testing.T) {
+	reviewer := &mockPullRequestReviewer{}
+	r := &Router{PullRequests: &mockPullRequestHost{}, Reviewer: reviewer}
+
+	result := r.executeAction(context.Background(), Action{Type: ActionCreatePR}, ActionContext{BeadID: "bead-1", ProjectID: "demo"})
+	if result.Status != "executed" || len(reviewer.opened) != 1 || reviewer.opened[0]["pr_number"] != 1 {
+		t.Fatalf("create_pr = %s, reviewer saw %v", result.Status, reviewer.opened)
+	}
+
+	result = r.executeAction(context.Background(), Action{Type: ActionCreatePR}, ActionContext{ProjectID: "real"})
+	if result.Status != "error" || len(reviewer.opened) != 1 {
+		t.Errorf("a failed create_pr must not be reviewed, reviewer saw %v", reviewer.opened)
+	}
+}
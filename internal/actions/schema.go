@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+
+	"github.com/jordanhubbard/loom/internal/staticanalysis"
 )
 
 const (
@@ -16,6 +18,7 @@ const (
 	ActionRunCommand    = "run_command"
 	ActionRunTests      = "run_tests"
 	ActionRunLinter     = "run_linter"
+	ActionStaticAnalysis = "static_analysis"
 	ActionBuildProject  = "build_project"
 	ActionCreateBead    = "create_bead"
 	ActionCloseBead     = "close_bead"
@@ -24,6 +27,7 @@ const (
 	ActionReadTree      = "read_tree"
 	ActionSearchText    = "search_text"
 	ActionApplyPatch    = "apply_patch"
+	ActionEditTransaction = "edit_transaction"
 	ActionGitStatus     = "git_status"
 	ActionGitDiff       = "git_diff"
 	ActionGitCommit       = "git_commit"
@@ -83,6 +87,7 @@ const (
 	// Agent communication actions
 	ActionSendAgentMessage = "send_agent_message"
 	ActionDelegateTask     = "delegate_task"
+	ActionHandoff          = "handoff"
 )
 
 type ActionEnvelope struct {
@@ -112,9 +117,16 @@ type Action struct {
 	Framework      string `json:"framework,omitempty"`
 	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
 
+	// Edit transaction fields
+	Edits  []Action `json:"edits,omitempty"`  // File edits applied as one unit by edit_transaction
+	Verify []string `json:"verify,omitempty"` // Commands that must pass for an edit_transaction to be kept
+
 	// Linter execution fields
 	Files []string `json:"files,omitempty"` // Specific files to lint
 
+	// Static analysis fields
+	Tools []string `json:"tools,omitempty"` // gosec, staticcheck, eslint (default: those that apply)
+
 	// Build execution fields
 	BuildTarget  string `json:"build_target,omitempty"`  // Build target (e.g., binary name)
 	BuildCommand string `json:"build_command,omitempty"` // Custom build command
@@ -197,6 +209,8 @@ type Action struct {
 
 	Bead *BeadPayload `json:"bead,omitempty"`
 
+	Handoff *HandoffPayload `json:"handoff,omitempty"`
+
 	BeadID     string `json:"bead_id,omitempty"`
 	Reason     string `json:"reason,omitempty"`     // Reason for bead operations or phase transitions
 	ReturnedTo string `json:"returned_to,omitempty"`
@@ -212,6 +226,16 @@ type BeadPayload struct {
 	Context     map[string]string `json:"context,omitempty"`
 }
 
+// HandoffPayload is the handoff document an agent leaves when it hands its
+// bead to another persona mid-task, e.g. a coder handing off to a reviewer
+type HandoffPayload struct {
+	ToPersona     string   `json:"to_persona"`
+	ModelTier     string   `json:"model_tier,omitempty"` // small, medium, large or xlarge
+	Summary       string   `json:"summary"`
+	OpenQuestions []string `json:"open_questions,omitempty"`
+	RelevantFiles []string `json:"relevant_files,omitempty"`
+}
+
 // ValidationError wraps action validation failures (JSON parsed OK, but required fields missing).
 // This is distinct from JSON parse errors — the model produced valid JSON but incomplete actions.
 type ValidationError struct {
@@ -401,6 +425,8 @@ func validateAction(action Action) error {
 		if action.Patch == "" {
 			return errors.New("apply_patch requires patch")
 		}
+	case ActionEditTransaction:
+		return validateTransaction(action)
 	case ActionDone:
 		// No required fields — agent signals work is complete
 	case ActionGitStatus, ActionGitDiff:
@@ -414,6 +440,22 @@ func validateAction(action Action) error {
 	case ActionCreatePR:
 		// pr_title and pr_body optional (auto-generated from bead)
 		// pr_base optional (defaults to main)
+	case ActionFetchPR, ActionReviewCode:
+		if action.PRNumber == 0 {
+			return fmt.Errorf("%s requires pr_number", action.Type)
+		}
+	case ActionAddPRComment:
+		if action.PRNumber == 0 || action.CommentBody == "" {
+			return errors.New("add_pr_comment requires pr_number and comment_body")
+		}
+	case ActionSubmitReview:
+		if action.PRNumber == 0 || action.ReviewEvent == "" || action.CommentBody == "" {
+			return errors.New("submit_review requires pr_number, review_event and comment_body")
+		}
+	case ActionRequestReview:
+		if action.PRNumber == 0 || action.Reviewer == "" {
+			return errors.New("request_review requires pr_number and reviewer")
+		}
 	case ActionGitMerge:
 		if action.SourceBranch == "" {
 			return errors.New("git_merge requires source_branch")
@@ -452,6 +494,12 @@ func validateAction(action Action) error {
 	case ActionRunLinter:
 		// All fields are optional - defaults will be used
 		// files, framework (auto-detect), timeout_seconds (default)
+	case ActionStaticAnalysis:
+		for _, tool := range action.Tools {
+			if !isStaticAnalysisTool(tool) {
+				return fmt.Errorf("static_analysis tool %q is not one of %s", tool, strings.Join(staticanalysis.Tools, ", "))
+			}
+		}
 	case ActionBuildProject:
 		// All fields are optional - defaults will be used
 		// build_target, framework (auto-detect), build_command, timeout_seconds (default)
@@ -470,6 +518,10 @@ func validateAction(action Action) error {
 		if action.BeadID == "" {
 			return errors.New("escalate_ceo requires bead_id")
 		}
+	case ActionHandoff:
+		if action.Handoff == nil || action.Handoff.ToPersona == "" || action.Handoff.Summary == "" {
+			return errors.New("handoff requires handoff.to_persona and handoff.summary")
+		}
 	case ActionApproveBead:
 		if action.BeadID == "" {
 			return errors.New("approve_bead requires bead_id")
@@ -593,3 +645,12 @@ func validateAction(action Action) error {
 
 	return nil
 }
+
+func isStaticAnalysisTool(tool string) bool {
+	for _, t := range staticanalysis.Tools {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
@@ -428,15 +428,40 @@ func TestValidateAction_BeadActions(t *testing.T) {
 	}
 }
 
-// PR review and agent communication action types are validated at the router level,
-// not by validateAction (which treats them as unknown). Test that they parse as valid JSON
-// and that the router handles them correctly (covered in router_pr_review_test.go and
-// router_agent_message_test.go / router_delegate_test.go).
+func TestValidateAction_PRActions(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  Action
+		wantErr bool
+	}{
+		{"fetch_pr valid", Action{Type: ActionFetchPR, PRNumber: 7}, false},
+		{"fetch_pr missing number", Action{Type: ActionFetchPR}, true},
+		{"review_code valid", Action{Type: ActionReviewCode, PRNumber: 7}, false},
+		{"review_code missing number", Action{Type: ActionReviewCode}, true},
+		{"add_pr_comment valid", Action{Type: ActionAddPRComment, PRNumber: 7, CommentBody: "nit"}, false},
+		{"add_pr_comment missing body", Action{Type: ActionAddPRComment, PRNumber: 7}, true},
+		{"submit_review valid", Action{Type: ActionSubmitReview, PRNumber: 7, ReviewEvent: "APPROVE", CommentBody: "LGTM"}, false},
+		{"submit_review missing event", Action{Type: ActionSubmitReview, PRNumber: 7, CommentBody: "LGTM"}, true},
+		{"request_review valid", Action{Type: ActionRequestReview, PRNumber: 7, Reviewer: "octocat"}, false},
+		{"request_review missing reviewer", Action{Type: ActionRequestReview, PRNumber: 7}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAction(tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateAction() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// Agent communication action types are validated at the router level, not by
+// validateAction (which treats them as unknown). The router's handling is
+// covered in router_agent_message_test.go and router_delegate_test.go.
 
-func TestValidateAction_PRActions_UnknownToValidator(t *testing.T) {
+func TestValidateAction_AgentActions_UnknownToValidator(t *testing.T) {
 	// These action types are handled by the router but not recognized by validateAction
 	unknownToValidator := []string{
-		ActionFetchPR, ActionReviewCode, ActionAddPRComment, ActionSubmitReview, ActionRequestReview,
 		ActionSendAgentMessage, ActionDelegateTask,
 	}
 	for _, actionType := range unknownToValidator {
@@ -18,6 +18,20 @@ type SimpleJSONAction struct {
 	Message string `json:"message,omitempty"`
 	Reason  string `json:"reason,omitempty"`
 	Notes   string `json:"notes,omitempty"`
+
+	// Handoff fields
+	Persona   string   `json:"persona,omitempty"`
+	Tier      string   `json:"tier,omitempty"`
+	Summary   string   `json:"summary,omitempty"`
+	Questions []string `json:"questions,omitempty"`
+	Files     []string `json:"files,omitempty"`
+
+	// Static analysis fields
+	Tools []string `json:"tools,omitempty"`
+
+	// Transaction fields
+	Edits  []SimpleJSONAction `json:"edits,omitempty"`
+	Verify []string           `json:"verify,omitempty"`
 }
 
 // ParseSimpleJSON parses the minimal JSON action format into an ActionEnvelope.
@@ -93,12 +107,36 @@ func simpleToAction(s SimpleJSONAction) (Action, error) {
 		}
 		return Action{Type: ActionWriteFile, Path: s.Path, Content: s.Content}, nil
 
+	case "transaction":
+		action := Action{Type: ActionEditTransaction, Verify: s.Verify, CommitMessage: s.Message}
+		for i, e := range s.Edits {
+			if e.Action != "edit" && e.Action != "write" {
+				return Action{}, &ValidationError{Err: fmt.Errorf("transaction edit %d must be an edit or a write, not '%s'", i+1, e.Action)}
+			}
+			edit, err := simpleToAction(e)
+			if err != nil {
+				return Action{}, err
+			}
+			action.Edits = append(action.Edits, edit)
+		}
+		if err := validateAction(action); err != nil {
+			return Action{}, &ValidationError{Err: err}
+		}
+		return action, nil
+
 	case "build":
 		return Action{Type: ActionBuildProject}, nil
 
 	case "test":
 		return Action{Type: ActionRunTests, TestPattern: s.Pattern}, nil
 
+	case "analyze":
+		action := Action{Type: ActionStaticAnalysis, Tools: s.Tools, Files: s.Files}
+		if err := validateAction(action); err != nil {
+			return Action{}, &ValidationError{Err: err}
+		}
+		return action, nil
+
 	case "bash":
 		if s.Command == "" {
 			return Action{}, &ValidationError{Err: fmt.Errorf("bash requires 'command'")}
@@ -123,7 +161,19 @@ func simpleToAction(s SimpleJSONAction) (Action, error) {
 	case "escalate":
 		return Action{Type: ActionEscalateCEO, Reason: s.Reason}, nil
 
+	case "handoff":
+		if s.Persona == "" || s.Summary == "" {
+			return Action{}, &ValidationError{Err: fmt.Errorf("handoff requires 'persona' and 'summary'")}
+		}
+		return Action{Type: ActionHandoff, Handoff: &HandoffPayload{
+			ToPersona:     s.Persona,
+			ModelTier:     s.Tier,
+			Summary:       s.Summary,
+			OpenQuestions: s.Questions,
+			RelevantFiles: s.Files,
+		}}, nil
+
 	default:
-		return Action{}, &ValidationError{Err: fmt.Errorf("unknown action '%s'. Use: scope, read, search, edit, write, build, test, bash, done, close_bead, git_commit, git_push", s.Action)}
+		return Action{}, &ValidationError{Err: fmt.Errorf("unknown action '%s'. Use: scope, read, search, edit, write, transaction, build, test, analyze, bash, done, close_bead, handoff, git_commit, git_push", s.Action)}
 	}
 }
@@ -134,6 +134,40 @@ func TestParseSimpleJSON_Write(t *testing.T) {
 	}
 }
 
+func TestParseSimpleJSON_Analyze(t *testing.T) {
+	env, err := ParseSimpleJSON([]byte(`{"action": "analyze", "tools": ["gosec"], "files": ["pkg/foo.go"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := env.Actions[0]
+	if a.Type != ActionStaticAnalysis || len(a.Tools) != 1 || a.Tools[0] != "gosec" || a.Files[0] != "pkg/foo.go" {
+		t.Errorf("unexpected action: %+v", a)
+	}
+
+	if _, err := ParseSimpleJSON([]byte(`{"action": "analyze", "tools": ["pylint"]}`)); err == nil {
+		t.Fatal("expected error for an unsupported tool")
+	}
+}
+
+func TestParseSimpleJSON_Transaction(t *testing.T) {
+	env, err := ParseSimpleJSON([]byte(`{"action": "transaction", "edits": [{"action": "edit", "path": "a.go", "old": "x", "new": "y"}, {"action": "write", "path": "b.go", "content": "package b"}], "verify": ["go build ./..."], "message": "refactor: move x"}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a := env.Actions[0]
+	if a.Type != ActionEditTransaction || len(a.Edits) != 2 || a.Edits[0].Type != ActionEditCode || a.Edits[1].Type != ActionWriteFile ||
+		a.Verify[0] != "go build ./..." || a.CommitMessage != "refactor: move x" {
+		t.Errorf("unexpected action: %+v", a)
+	}
+
+	if _, err := ParseSimpleJSON([]byte(`{"action": "transaction", "edits": [{"action": "bash", "command": "make"}]}`)); err == nil {
+		t.Fatal("expected error for a command inside a transaction")
+	}
+	if _, err := ParseSimpleJSON([]byte(`{"action": "transaction"}`)); err == nil {
+		t.Fatal("expected error for a transaction without edits")
+	}
+}
+
 func TestParseSimpleJSON_WriteMissingFields(t *testing.T) {
 	_, err := ParseSimpleJSON([]byte(`{"action": "write", "path": "foo.go"}`))
 	if err == nil {
@@ -254,6 +288,21 @@ func TestParseSimpleJSON_Escalate(t *testing.T) {
 	}
 }
 
+func TestParseSimpleJSON_Handoff(t *testing.T) {
+	env, err := ParseSimpleJSON([]byte(`{"action": "handoff", "persona": "code-reviewer", "tier": "large", "summary": "ready for review", "questions": ["naming?"], "files": ["a.go"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	h := env.Actions[0].Handoff
+	if env.Actions[0].Type != ActionHandoff || h == nil || h.ToPersona != "code-reviewer" || h.ModelTier != "large" || len(h.OpenQuestions) != 1 || len(h.RelevantFiles) != 1 {
+		t.Errorf("unexpected handoff action %+v", env.Actions[0])
+	}
+
+	if _, err := ParseSimpleJSON([]byte(`{"action": "handoff", "persona": "code-reviewer"}`)); err == nil {
+		t.Error("expected a handoff without a summary to be rejected")
+	}
+}
+
 func TestParseSimpleJSON_UnknownAction(t *testing.T) {
 	_, err := ParseSimpleJSON([]byte(`{"action": "fly_to_moon"}`))
 	if err == nil {
@@ -41,11 +41,15 @@ UNCOMMITTED WORK IS LOST. Always git_commit before done.
 ### Change
 {"action": "edit", "path": "file.go", "old": "exact text to find", "new": "replacement text"}
 {"action": "write", "path": "file.go", "content": "full file content"}
+{"action": "transaction", "edits": [{"action": "edit", ...}, {"action": "write", ...}], "verify": ["go build ./..."], "message": "refactor: ..."}
+  — Apply several edits as one unit: all are rolled back if one fails or a verify command fails; committed with "message" if given
 
 ### Verify
 {"action": "build"}                                      — Build the project
 {"action": "test"}                                       — Run all tests
 {"action": "test", "pattern": "TestFoo"}                 — Run specific tests
+{"action": "analyze"}                                    — Run gosec/staticcheck/eslint, get structured findings
+  Optional: "tools": ["gosec"], "files": ["pkg/foo.go"]
 {"action": "bash", "command": "go vet ./..."}            — Run shell command
 
 ### Land
@@ -53,6 +57,10 @@ UNCOMMITTED WORK IS LOST. Always git_commit before done.
 {"action": "git_push"}                                    — Push to remote
 {"action": "done", "reason": "summary of work done"}     — Signal completion
 
+### Hand off
+{"action": "handoff", "persona": "code-reviewer", "summary": "what is done, what is left"} — Hand the bead to another persona and stop
+  Optional: "questions": ["..."], "files": ["file.go"], "tier": "small|medium|large|xlarge"
+
 ## Rules
 
 - ONE action per response. Use "notes" for reasoning.
@@ -0,0 +1,208 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/patch"
+)
+
+// transactionEditTypes are the edits an edit_transaction may group
+var transactionEditTypes = map[string]bool{
+	ActionWriteFile:  true,
+	ActionEditCode:   true,
+	ActionApplyPatch: true,
+	ActionDeleteFile: true,
+	ActionMoveFile:   true,
+}
+
+// validateTransaction checks an edit_transaction and each of its edits
+func validateTransaction(action Action) error {
+	if len(action.Edits) == 0 {
+		return errors.New("edit_transaction requires edits")
+	}
+	for i, edit := range action.Edits {
+		if !transactionEditTypes[edit.Type] {
+			return fmt.Errorf("edit_transaction edit %d: %q cannot be part of a transaction", i+1, edit.Type)
+		}
+		if edit.Type == ActionEditCode && edit.Path != "" && edit.OldText != "" {
+			continue
+		}
+		if err := validateAction(edit); err != nil {
+			return fmt.Errorf("edit_transaction edit %d: %w", i+1, err)
+		}
+	}
+	for _, command := range action.Verify {
+		if strings.TrimSpace(command) == "" {
+			return errors.New("edit_transaction verify commands must not be empty")
+		}
+	}
+	return nil
+}
+
+// snapshot is a file's content before a transaction touched it
+type snapshot struct {
+	path    string
+	content string
+	existed bool
+}
+
+// executeTransaction applies a group of edits as one unit: every file they
+// touch is saved first, the edits are applied in order, the verify commands
+// run, and the change is committed when a commit message is given. If any
+// step fails every file is restored, so the branch never holds half of it.
+func (r *Router) executeTransaction(ctx context.Context, action Action, actx ActionContext) Result {
+	fail := func(message string) Result {
+		return Result{ActionType: action.Type, Status: "error", Message: message}
+	}
+	if r.Files == nil {
+		return fail("file manager not configured")
+	}
+	if len(action.Verify) > 0 && r.Commands == nil {
+		return fail("edit_transaction verify commands need a command executor")
+	}
+	if action.CommitMessage != "" && r.Git == nil {
+		return fail("git operator not configured")
+	}
+
+	var paths []string
+	for i, edit := range action.Edits {
+		touched, err := editPaths(edit)
+		if err != nil {
+			return fail(fmt.Sprintf("edit %d (%s): %v", i+1, edit.Type, err))
+		}
+		paths = appendUnique(paths, touched...)
+	}
+	var saved []snapshot
+	for _, path := range paths {
+		res, err := r.Files.ReadFile(ctx, actx.ProjectID, path)
+		switch {
+		case err == nil:
+			saved = append(saved, snapshot{path: path, content: res.Content, existed: true})
+		case errors.Is(err, fs.ErrNotExist):
+			saved = append(saved, snapshot{path: path})
+		default:
+			return fail(fmt.Sprintf("cannot save %s before editing it: %v", path, err))
+		}
+	}
+
+	abort := func(message string) Result {
+		if errs := r.rollback(ctx, actx, saved); len(errs) > 0 {
+			message += "; rolling back failed: " + strings.Join(errs, "; ")
+		} else {
+			message += "; all edits were rolled back"
+		}
+		return Result{ActionType: action.Type, Status: "error", Message: message, Metadata: map[string]interface{}{"files": paths}}
+	}
+
+	for i, edit := range action.Edits {
+		if allowed, reason, _ := r.checkPolicy(ctx, edit, actx); !allowed {
+			return abort(fmt.Sprintf("edit %d (%s) refused: %s", i+1, edit.Type, reason))
+		}
+		if res := r.executeAction(ctx, edit, actx); res.Status != "executed" {
+			return abort(fmt.Sprintf("edit %d (%s) failed: %s", i+1, edit.Type, res.Message))
+		}
+	}
+
+	for _, command := range action.Verify {
+		res, err := r.Commands.ExecuteCommand(ctx, executor.ExecuteCommandRequest{
+			AgentID:   actx.AgentID,
+			BeadID:    actx.BeadID,
+			ProjectID: actx.ProjectID,
+			Command:   command,
+			Context: map[string]interface{}{
+				"action_type": action.Type,
+				"reason":      "verify edit transaction",
+			},
+		})
+		if err != nil {
+			return abort(fmt.Sprintf("verify %q failed: %v", command, err))
+		}
+		if res.ExitCode != 0 {
+			output := strings.TrimSpace(res.Stdout + "\n" + res.Stderr)
+			return abort(fmt.Sprintf("verify %q exited with %d:\n%s", command, res.ExitCode, truncateOutput(output, maxBuildOutputLen)))
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"files":    paths,
+		"edits":    len(action.Edits),
+		"verified": action.Verify,
+	}
+	if action.CommitMessage != "" {
+		commit, err := r.Git.Commit(ctx, actx.BeadID, actx.AgentID, action.CommitMessage, paths, false)
+		if err != nil {
+			return abort(fmt.Sprintf("commit failed: %v", err))
+		}
+		metadata["commit"] = commit
+	}
+
+	message := fmt.Sprintf("applied %d edits to %d files", len(action.Edits), len(paths))
+	if len(action.Verify) > 0 {
+		message += fmt.Sprintf(", %d verify commands passed", len(action.Verify))
+	}
+	if action.CommitMessage != "" {
+		message += ", committed"
+	}
+	return Result{ActionType: action.Type, Status: "executed", Message: message, Metadata: metadata}
+}
+
+// rollback restores saved files, deleting those that did not exist
+func (r *Router) rollback(ctx context.Context, actx ActionContext, saved []snapshot) []string {
+	var errs []string
+	for i := len(saved) - 1; i >= 0; i-- {
+		s := saved[i]
+		var err error
+		if s.existed {
+			_, err = r.Files.WriteFile(ctx, actx.ProjectID, s.path, s.content)
+		} else if _, readErr := r.Files.ReadFile(ctx, actx.ProjectID, s.path); readErr == nil {
+			err = r.Files.DeleteFile(ctx, actx.ProjectID, s.path)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", s.path, err))
+		}
+	}
+	return errs
+}
+
+// editPaths returns the files an edit may change
+func editPaths(edit Action) ([]string, error) {
+	switch edit.Type {
+	case ActionMoveFile:
+		return []string{edit.SourcePath, edit.TargetPath}, nil
+	case ActionApplyPatch, ActionEditCode:
+		if edit.Type == ActionEditCode && edit.OldText != "" {
+			return []string{edit.Path}, nil
+		}
+		parsed, err := patch.Parse(edit.Patch)
+		if err != nil {
+			return nil, fmt.Errorf("cannot tell which files the patch changes: %w", err)
+		}
+		var paths []string
+		for _, fp := range parsed {
+			paths = append(paths, fp.Path)
+		}
+		return paths, nil
+	}
+	return []string{edit.Path}, nil
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		found := false
+		for _, existing := range list {
+			if existing == item {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, item)
+		}
+	}
+	return list
+}
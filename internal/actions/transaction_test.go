@@ -0,0 +1,134 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/files"
+)
+
+type dirResolver string
+
+func (d dirResolver) GetProjectWorkDir(projectID string) string { return string(d) }
+
+func newTransactionRouter(t *testing.T) (*Router, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "calc.go"), []byte("package calc\n\nfunc Add(a, b int) int { return a + b }\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &Router{Files: files.NewManager(dirResolver(dir))}, dir
+}
+
+func transactionEdits() []Action {
+	return []Action{
+		{Type: ActionWriteFile, Path: "sub.go", Content: "package calc\n\nfunc Sub(a, b int) int { return a - b }\n"},
+		{Type: ActionEditCode, Path: "calc.go", OldText: "return a + b", NewText: "return b + a"},
+	}
+}
+
+func TestRouter_EditTransaction(t *testing.T) {
+	r, dir := newTransactionRouter(t)
+	commands := &mockCommandExecutor{}
+	r.Commands = commands
+	r.Git = &mockGitOperator{result: map[string]interface{}{"commit_sha": "abc123"}}
+
+	action := Action{Type: ActionEditTransaction, Edits: transactionEdits(), Verify: []string{"go build ./..."}, CommitMessage: "refactor: split calc"}
+	result := r.executeAction(context.Background(), action, ActionContext{ProjectID: "p1", BeadID: "b1"})
+	if result.Status != "executed" {
+		t.Fatalf("expected executed, got %s: %s", result.Status, result.Message)
+	}
+	if result.Message != "applied 2 edits to 2 files, 1 verify commands passed, committed" {
+		t.Errorf("message = %q", result.Message)
+	}
+	if paths, _ := result.Metadata["files"].([]string); len(paths) != 2 || paths[0] != "sub.go" || paths[1] != "calc.go" {
+		t.Errorf("files = %v", result.Metadata["files"])
+	}
+	if commands.lastReq.Command != "go build ./..." || commands.lastReq.BeadID != "b1" {
+		t.Errorf("verify request = %+v", commands.lastReq)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "calc.go")); !strings.Contains(string(data), "return b + a") {
+		t.Errorf("calc.go = %q", data)
+	}
+}
+
+func TestRouter_EditTransaction_RollsBack(t *testing.T) {
+	tests := []struct {
+		name     string
+		edits    []Action
+		commands *mockCommandExecutor
+		git      *mockGitOperator
+		want     string
+	}{
+		{
+			name:  "an edit fails",
+			edits: append(transactionEdits(), Action{Type: ActionEditCode, Path: "calc.go", OldText: "func Mul", NewText: "x"}),
+			want:  "edit 3 (edit_code) failed: OLD text not found in calc.go",
+		},
+		{
+			name:     "verification fails",
+			edits:    transactionEdits(),
+			commands: &mockCommandExecutor{result: &executor.ExecuteCommandResult{ExitCode: 2, Stderr: "calc.go:3: undefined: b"}},
+			want:     "verify \"go test ./...\" exited with 2:\ncalc.go:3: undefined: b",
+		},
+		{
+			name:  "the commit fails",
+			edits: transactionEdits(),
+			git:   &mockGitOperator{err: errors.New("nothing to commit")},
+			want:  "commit failed: nothing to commit",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, dir := newTransactionRouter(t)
+			action := Action{Type: ActionEditTransaction, Edits: tt.edits}
+			if tt.commands != nil {
+				r.Commands = tt.commands
+				action.Verify = []string{"go test ./..."}
+			}
+			if tt.git != nil {
+				r.Git = tt.git
+				action.CommitMessage = "refactor: split calc"
+			}
+			result := r.executeAction(context.Background(), action, ActionContext{ProjectID: "p1"})
+			if result.Status != "error" || !strings.HasPrefix(result.Message, tt.want) || !strings.HasSuffix(result.Message, "all edits were rolled back") {
+				t.Fatalf("result = %s: %s", result.Status, result.Message)
+			}
+			if data, _ := os.ReadFile(filepath.Join(dir, "calc.go")); !strings.Contains(string(data), "return a + b") {
+				t.Errorf("calc.go was not restored: %q", data)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "sub.go")); !os.IsNotExist(err) {
+				t.Errorf("sub.go was not removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateEditTransaction(t *testing.T) {
+	tests := []struct {
+		name   string
+		action Action
+		want   string
+	}{
+		{"no edits", Action{Type: ActionEditTransaction}, "edit_transaction requires edits"},
+		{"a command as an edit", Action{Type: ActionEditTransaction, Edits: []Action{{Type: ActionRunCommand, Command: "rm -rf ."}}}, `"run_command" cannot be part of a transaction`},
+		{"an invalid edit", Action{Type: ActionEditTransaction, Edits: []Action{{Type: ActionWriteFile, Path: "a.go"}}}, "edit 1: write_file requires path and content"},
+		{"an empty verify command", Action{Type: ActionEditTransaction, Edits: transactionEdits(), Verify: []string{" "}}, "verify commands must not be empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(&ActionEnvelope{Actions: []Action{tt.action}})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate = %v, want %q", err, tt.want)
+			}
+		})
+	}
+	if err := Validate(&ActionEnvelope{Actions: []Action{{Type: ActionEditTransaction, Edits: transactionEdits()}}}); err != nil {
+		t.Errorf("a valid transaction was refused: %v", err)
+	}
+}
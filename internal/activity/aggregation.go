@@ -0,0 +1,301 @@
+package activity
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+// Fields aggregation rules group events by
+const (
+	GroupByProject  = "project"
+	GroupByActor    = "actor"
+	GroupByAgent    = "agent"
+	GroupByBead     = "bead"
+	GroupByResource = "resource"
+)
+
+// AggregationRule folds bursts of an event type into one activity: an
+// event joins the latest activity of its group when it comes within Window
+// of that activity's latest event. Each event joining moves the window, so
+// "agent-3 read 42 files" stays one activity for as long as the reads keep
+// coming.
+type AggregationRule struct {
+	Window    time.Duration
+	GroupBy   []string // Values an event must share with the activity it joins; default project and actor
+	MaxEvents int      // Events an activity stands for before the next starts; 0 for no limit
+}
+
+// DefaultAggregationRules aggregates bursts of created beads and spawned
+// agents by project and actor
+func DefaultAggregationRules() map[string]AggregationRule {
+	return map[string]AggregationRule{
+		"bead.created":  {Window: 5 * time.Minute, GroupBy: []string{GroupByProject, GroupByActor}},
+		"agent.spawned": {Window: 5 * time.Minute, GroupBy: []string{GroupByProject, GroupByActor}},
+	}
+}
+
+// SetAggregationRules replaces the aggregation rules, by event type. Events
+// of types without a rule, or whose rule has no window, each get their own
+// activity.
+func (m *Manager) SetAggregationRules(rules map[string]AggregationRule) {
+	m.aggregationMu.Lock()
+	defer m.aggregationMu.Unlock()
+	m.rules = rules
+	m.aggregationCache = make(map[string]*Activity)
+}
+
+// AggregationRules returns the aggregation rules, by event type
+func (m *Manager) AggregationRules() map[string]AggregationRule {
+	m.aggregationMu.RLock()
+	defer m.aggregationMu.RUnlock()
+	rules := make(map[string]AggregationRule, len(m.rules))
+	for eventType, rule := range m.rules {
+		rules[eventType] = rule
+	}
+	return rules
+}
+
+// aggregationKey names the group an activity aggregates in under rule
+func aggregationKey(a *Activity, rule AggregationRule) string {
+	groupBy := rule.GroupBy
+	if len(groupBy) == 0 {
+		groupBy = []string{GroupByProject, GroupByActor}
+	}
+	parts := []string{a.EventType, a.OrgID}
+	for _, field := range groupBy {
+		var value string
+		switch field {
+		case GroupByProject:
+			value = a.ProjectID
+		case GroupByActor:
+			value = a.ActorID
+		case GroupByAgent:
+			value = a.AgentID
+		case GroupByBead:
+			value = a.BeadID
+		case GroupByResource:
+			value = a.ResourceType + ":" + a.ResourceID
+		}
+		parts = append(parts, field+"="+value)
+	}
+	return strings.Join(parts, "|")
+}
+
+// joins reports whether an event at ts joins aggregate under rule
+func joins(aggregate *Activity, ts time.Time, rule AggregationRule) bool {
+	if rule.MaxEvents > 0 && aggregate.AggregationCount >= rule.MaxEvents {
+		return false
+	}
+	return ts.Sub(aggregate.LastEventAt) <= rule.Window
+}
+
+// recordAggregated records an activity under its aggregation rule: it
+// joins the latest activity of its group when that is recent enough, and
+// starts a new one otherwise. Either way the event is kept so the
+// aggregated activity can be expanded.
+func (m *Manager) recordAggregated(activity *Activity, rule AggregationRule) error {
+	activity.AggregationKey = aggregationKey(activity, rule)
+	activity.IsAggregated = true
+
+	aggregate, err := m.aggregate(activity, rule)
+	if aggregate != nil {
+		m.broadcastActivity(aggregate)
+	}
+	return err
+}
+
+// aggregate stores activity as a new aggregate or joins it to its group's
+// latest, returning the aggregate once it is stored. Cached aggregates are
+// never changed in place: a joined one is copied, and the copy replaces it
+// once the database has it.
+func (m *Manager) aggregate(activity *Activity, rule AggregationRule) (*Activity, error) {
+	m.recordMu.Lock()
+	defer m.recordMu.Unlock()
+
+	m.aggregationMu.RLock()
+	cached := m.aggregationCache[activity.AggregationKey]
+	m.aggregationMu.RUnlock()
+
+	var aggregate *Activity
+	if cached != nil && joins(cached, activity.Timestamp, rule) {
+		joined := *cached
+		aggregate = &joined
+	} else {
+		existing, err := m.db.GetRecentAggregatableActivity(activity.AggregationKey, activity.Timestamp.Add(-rule.Window))
+		if err != nil {
+			return nil, fmt.Errorf("failed to find the activity to aggregate into: %w", err)
+		}
+		if existing != nil {
+			if a := FromDBActivity(existing); joins(a, activity.Timestamp, rule) {
+				aggregate = a
+			}
+		}
+	}
+
+	if aggregate == nil {
+		if err := m.storeActivity(activity); err != nil {
+			return nil, err
+		}
+		m.cacheAggregate(activity.AggregationKey, activity)
+		return activity, m.recordEvent(activity, activity)
+	}
+
+	aggregate.AggregationCount++
+	if activity.Timestamp.After(aggregate.LastEventAt) {
+		aggregate.LastEventAt = activity.Timestamp
+	}
+	aggregate.Summary = summarize(aggregate)
+	if err := m.db.UpdateAggregatedActivity(aggregate.ID, aggregate.AggregationCount, aggregate.LastEventAt); err != nil {
+		return nil, fmt.Errorf("failed to update aggregated activity: %w", err)
+	}
+	m.cacheAggregate(activity.AggregationKey, aggregate)
+	return aggregate, m.recordEvent(aggregate, activity)
+}
+
+// aggregationSweepInterval is how often cached aggregates whose window has
+// passed are dropped
+const aggregationSweepInterval = time.Minute
+
+// cacheAggregate makes aggregate the latest activity of its group
+func (m *Manager) cacheAggregate(key string, aggregate *Activity) {
+	m.aggregationMu.Lock()
+	defer m.aggregationMu.Unlock()
+	if now := time.Now(); now.Sub(m.aggregationSwept) >= aggregationSweepInterval {
+		m.sweepAggregates(now)
+		m.aggregationSwept = now
+	}
+	m.aggregationCache[key] = aggregate
+}
+
+// sweepAggregates drops the cached aggregates whose window has passed, so
+// groups of beads and resources long done do not pile up. An event that
+// still joins one, arriving late, finds it in the database.
+func (m *Manager) sweepAggregates(now time.Time) {
+	for key, a := range m.aggregationCache {
+		if rule, ok := m.rules[a.EventType]; !ok || now.Sub(a.LastEventAt) > rule.Window {
+			delete(m.aggregationCache, key)
+		}
+	}
+}
+
+// recordEvent keeps the event of activity as one aggregate stands for
+func (m *Manager) recordEvent(aggregate, activity *Activity) error {
+	e := &database.ActivityEvent{
+		ID:            uuid.New().String(),
+		ActivityID:    aggregate.ID,
+		EventID:       activity.EventID,
+		Timestamp:     activity.Timestamp,
+		ActorID:       activity.ActorID,
+		AgentID:       activity.AgentID,
+		BeadID:        activity.BeadID,
+		ResourceID:    activity.ResourceID,
+		ResourceTitle: activity.ResourceTitle,
+	}
+	if activity.Metadata != nil {
+		if data, err := json.Marshal(activity.Metadata); err == nil {
+			e.MetadataJSON = string(data)
+		}
+	}
+	return m.db.CreateActivityEvent(e)
+}
+
+// summarize describes an aggregated activity, such as "agent-3 created 42
+// beads in 5 minutes"; it is empty for an activity standing for one event
+func summarize(a *Activity) string {
+	if a.AggregationCount < 2 {
+		return ""
+	}
+	span := a.LastEventAt.Sub(a.Timestamp)
+	var within string
+	switch {
+	case span < time.Minute:
+		within = "under a minute"
+	case span < 2*time.Minute:
+		within = "1 minute"
+	case span < 2*time.Hour:
+		within = fmt.Sprintf("%d minutes", int(span.Minutes()))
+	default:
+		within = fmt.Sprintf("%d hours", int(span.Hours()))
+	}
+	actor := a.ActorID
+	if actor == "" {
+		actor = a.AgentID
+	}
+	if actor == "" {
+		return fmt.Sprintf("%d %ss %s in %s", a.AggregationCount, a.ResourceType, a.Action, within)
+	}
+	return fmt.Sprintf("%s %s %d %ss in %s", actor, a.Action, a.AggregationCount, a.ResourceType, within)
+}
+
+// Event is one of the events an aggregated activity stands for
+type Event struct {
+	ID            string                 `json:"id"`
+	EventID       string                 `json:"event_id,omitempty"`
+	Timestamp     time.Time              `json:"timestamp"`
+	ActorID       string                 `json:"actor_id,omitempty"`
+	AgentID       string                 `json:"agent_id,omitempty"`
+	BeadID        string                 `json:"bead_id,omitempty"`
+	ResourceID    string                 `json:"resource_id"`
+	ResourceTitle string                 `json:"resource_title,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// EventFilters pages through the events of an aggregated activity
+type EventFilters struct {
+	Limit      int
+	Offset     int
+	After      []interface{} // (timestamp, id) of the last event of the previous page
+	Descending bool          // newest first; oldest first by default
+}
+
+// GetActivity returns an activity, or nil when there is none
+func (m *Manager) GetActivity(id string) (*Activity, error) {
+	dbActivity, err := m.db.GetActivity(id)
+	if err != nil || dbActivity == nil {
+		return nil, err
+	}
+	return fromDBWithMetadata(dbActivity), nil
+}
+
+// GetEvents expands an aggregated activity into the events it stands for,
+// returning a page of them and how many there are. Activities aggregated
+// before events were kept have none.
+func (m *Manager) GetEvents(activityID string, f EventFilters) ([]*Event, int, error) {
+	total, err := m.db.CountActivityEvents(activityID)
+	if err != nil {
+		return nil, 0, err
+	}
+	dbEvents, err := m.db.ListActivityEvents(database.ActivityEventFilters{
+		ActivityID: activityID,
+		Limit:      f.Limit,
+		Offset:     f.Offset,
+		After:      f.After,
+		Descending: f.Descending,
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	events := make([]*Event, 0, len(dbEvents))
+	for _, e := range dbEvents {
+		event := &Event{
+			ID:            e.ID,
+			EventID:       e.EventID,
+			Timestamp:     e.Timestamp,
+			ActorID:       e.ActorID,
+			AgentID:       e.AgentID,
+			BeadID:        e.BeadID,
+			ResourceID:    e.ResourceID,
+			ResourceTitle: e.ResourceTitle,
+		}
+		if e.MetadataJSON != "" {
+			_ = json.Unmarshal([]byte(e.MetadataJSON), &event.Metadata)
+		}
+		events = append(events, event)
+	}
+	return events, total, nil
+}
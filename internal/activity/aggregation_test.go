@@ -0,0 +1,168 @@
+package activity
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "activity.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.UpsertProject(&models.Project{ID: "proj-1", Name: "proj-1", Branch: "main", BeadsPath: ".beads"}); err != nil {
+		t.Fatalf("UpsertProject failed: %v", err)
+	}
+	return NewManager(db, nil)
+}
+
+func beadCreated(id, actor string, at time.Time) *eventbus.Event {
+	return &eventbus.Event{
+		ID:        "ev-" + id,
+		Type:      "bead.created",
+		Timestamp: at,
+		Source:    "test",
+		ProjectID: "proj-1",
+		Data:      map[string]interface{}{"bead_id": id, "actor_id": actor, "title": "Bead " + id},
+	}
+}
+
+func TestRecordActivity_SlidingWindow(t *testing.T) {
+	m := newTestManager(t)
+	base := time.Now().Add(-time.Hour)
+
+	// Four minutes apart: each event is within five minutes of the last,
+	// though the burst spans twelve
+	for i, offset := range []time.Duration{0, 4, 8, 12} {
+		event := beadCreated(string(rune('a'+i)), "agent-3", base.Add(offset*time.Minute))
+		if err := m.RecordActivity(event); err != nil {
+			t.Fatalf("RecordActivity failed: %v", err)
+		}
+	}
+	// A gap longer than the window starts a new activity
+	if err := m.RecordActivity(beadCreated("e", "agent-3", base.Add(30*time.Minute))); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+	// Another actor aggregates apart
+	if err := m.RecordActivity(beadCreated("f", "agent-4", base.Add(13*time.Minute))); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+
+	activities, err := m.GetActivities(ActivityFilters{Ascending: true})
+	if err != nil {
+		t.Fatalf("GetActivities failed: %v", err)
+	}
+	if len(activities) != 3 {
+		t.Fatalf("expected 3 activities, got %d", len(activities))
+	}
+	burst := activities[0]
+	if burst.AggregationCount != 4 || !burst.LastEventAt.Equal(base.Add(12*time.Minute)) {
+		t.Errorf("burst = %d events until %v", burst.AggregationCount, burst.LastEventAt)
+	}
+	if burst.Summary != "agent-3 created 4 beads in 12 minutes" {
+		t.Errorf("Summary = %q", burst.Summary)
+	}
+
+	events, total, err := m.GetEvents(burst.ID, EventFilters{})
+	if err != nil {
+		t.Fatalf("GetEvents failed: %v", err)
+	}
+	if total != 4 || len(events) != 4 || events[0].ResourceID != "a" || events[3].ResourceID != "d" {
+		t.Fatalf("expanded %d of %d events: %+v", len(events), total, events)
+	}
+	if events[1].Metadata["title"] != "Bead b" {
+		t.Errorf("event metadata = %v", events[1].Metadata)
+	}
+}
+
+func TestRecordActivity_Rules(t *testing.T) {
+	m := newTestManager(t)
+	m.SetAggregationRules(map[string]AggregationRule{
+		"bead.created": {Window: time.Hour, GroupBy: []string{GroupByProject}, MaxEvents: 2},
+	})
+	base := time.Now().Add(-time.Hour)
+	for i, actor := range []string{"agent-1", "agent-2", "agent-3"} {
+		if err := m.RecordActivity(beadCreated(actor, actor, base.Add(time.Duration(i)*time.Minute))); err != nil {
+			t.Fatalf("RecordActivity failed: %v", err)
+		}
+	}
+
+	activities, err := m.GetActivities(ActivityFilters{Ascending: true})
+	if err != nil {
+		t.Fatalf("GetActivities failed: %v", err)
+	}
+	// Grouped by project alone, across actors, two events at most
+	if len(activities) != 2 || activities[0].AggregationCount != 2 || activities[1].AggregationCount != 1 {
+		t.Fatalf("unexpected activities: %+v", activities)
+	}
+
+	// Without a rule, every event is its own activity
+	m.SetAggregationRules(nil)
+	for _, id := range []string{"x", "y"} {
+		if err := m.RecordActivity(beadCreated(id, "agent-1", time.Now())); err != nil {
+			t.Fatalf("RecordActivity failed: %v", err)
+		}
+	}
+	if n, err := m.CountActivities(ActivityFilters{}); err != nil || n != 4 {
+		t.Errorf("CountActivities = %d, %v", n, err)
+	}
+}
+
+func TestRecordActivity_FailedJoinLeavesCache(t *testing.T) {
+	m := newTestManager(t)
+	base := time.Now().Add(-time.Hour)
+	if err := m.RecordActivity(beadCreated("a", "agent-3", base)); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+
+	m.db.Close()
+	if err := m.RecordActivity(beadCreated("b", "agent-3", base.Add(time.Minute))); err == nil {
+		t.Fatal("expected joining to fail with the database closed")
+	}
+	for _, cached := range m.aggregationCache {
+		if cached.AggregationCount != 1 || !cached.LastEventAt.Equal(base) {
+			t.Errorf("failed join changed the cached aggregate: %d events until %v", cached.AggregationCount, cached.LastEventAt)
+		}
+	}
+}
+
+func TestRecordActivity_SweepsExpiredAggregates(t *testing.T) {
+	m := newTestManager(t)
+	base := time.Now().Add(-time.Hour)
+	for _, actor := range []string{"agent-1", "agent-2"} {
+		if err := m.RecordActivity(beadCreated(actor, actor, base)); err != nil {
+			t.Fatalf("RecordActivity failed: %v", err)
+		}
+	}
+	if len(m.aggregationCache) != 2 {
+		t.Fatalf("expected 2 cached aggregates, got %d", len(m.aggregationCache))
+	}
+
+	// The next sweep drops the groups whose window has passed
+	m.aggregationSwept = time.Time{}
+	if err := m.RecordActivity(beadCreated("now", "agent-3", time.Now())); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+	if len(m.aggregationCache) != 1 {
+		t.Fatalf("expected only the current aggregate cached, got %d", len(m.aggregationCache))
+	}
+
+	// A late event of a dropped group still joins it, from the database
+	if err := m.RecordActivity(beadCreated("late", "agent-1", base.Add(time.Minute))); err != nil {
+		t.Fatalf("RecordActivity failed: %v", err)
+	}
+	activities, err := m.GetActivities(ActivityFilters{Ascending: true})
+	if err != nil {
+		t.Fatalf("GetActivities failed: %v", err)
+	}
+	if len(activities) != 3 || activities[0].AggregationCount+activities[1].AggregationCount != 3 {
+		t.Fatalf("expected the late event to join its group, got %d activities", len(activities))
+	}
+}
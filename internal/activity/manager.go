@@ -9,13 +9,10 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
 	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
 )
 
-const (
-	aggregationWindow = 5 * time.Minute
-)
-
 // Manager handles activity feed logic
 type Manager struct {
 	db               *database.Database
@@ -23,8 +20,44 @@ type Manager struct {
 	subscribers      map[string]chan *Activity
 	subscribersMu    sync.RWMutex
 	eventFilterSet   map[string]bool
-	aggregationCache map[string]*Activity
+	aggregationCache map[string]*Activity // Latest activity of each aggregation group
+	aggregationSwept time.Time            // When aggregates no event can join were last dropped
 	aggregationMu    sync.RWMutex
+	rules            map[string]AggregationRule // By event type
+	recordMu         sync.Mutex                 // Serializes aggregation, so events of a group join one at a time
+
+	orgMu sync.RWMutex
+	orgOf OrgResolver // nil places every activity in the default organization
+}
+
+// OrgResolver returns the organization owning a project or provider, or ""
+// when it does not know it
+type OrgResolver func(projectID, providerID string) string
+
+// SetOrgResolver sets how activities are placed in organizations
+func (m *Manager) SetOrgResolver(resolve OrgResolver) {
+	m.orgMu.Lock()
+	defer m.orgMu.Unlock()
+	m.orgOf = resolve
+}
+
+// resolveOrg places an activity in an organization: the one its event
+// names, else the owner of its project or provider. Activities of shared
+// resources, and of none, belong to the default organization, whose users
+// run the system.
+func (m *Manager) resolveOrg(event *eventbus.Event, activity *Activity) string {
+	if orgID, ok := event.Data["org_id"].(string); ok && orgID != "" {
+		return orgID
+	}
+	m.orgMu.RLock()
+	resolve := m.orgOf
+	m.orgMu.RUnlock()
+	if resolve != nil {
+		if orgID := resolve(activity.ProjectID, activity.ProviderID); orgID != "" {
+			return orgID
+		}
+	}
+	return org.DefaultID
 }
 
 // NewManager creates a new activity manager
@@ -35,6 +68,7 @@ func NewManager(db *database.Database, eventBus *eventbus.EventBus) *Manager {
 		subscribers:      make(map[string]chan *Activity),
 		eventFilterSet:   buildEventFilterSet(),
 		aggregationCache: make(map[string]*Activity),
+		rules:            DefaultAggregationRules(),
 	}
 
 	// Subscribe to EventBus
@@ -49,10 +83,26 @@ func NewManager(db *database.Database, eventBus *eventbus.EventBus) *Manager {
 func buildEventFilterSet() map[string]bool {
 	return map[string]bool{
 		// Bead events
-		"bead.created":       true,
-		"bead.assigned":      true,
-		"bead.status_change": true,
-		"bead.completed":     true,
+		"bead.created":               true,
+		"bead.assigned":              true,
+		"bead.status_change":         true,
+		"bead.completed":             true,
+		"bead.handed_off":            true,
+		"bead.verification_failed":   true,
+		"bead.ci_status":             true,
+		"bead.guidance_acknowledged": true,
+		"bead.plan_proposed":         true,
+		"bead.plan_approved":         true,
+		"bead.plan_rejected":         true,
+		"bead.patch_proposed":        true,
+		"bead.patch_approved":        true,
+		"bead.patch_rejected":        true,
+		"bead.undone":                true,
+		"bead.files_locked":          true,
+		"bead.files_unlocked":        true,
+		"bead.file_lock_stolen":      true,
+		"bead.file_lock_deadlock":    true,
+		"bead.model_switched":        true,
 
 		// Agent events
 		"agent.spawned":       true,
@@ -68,6 +118,8 @@ func buildEventFilterSet() map[string]bool {
 		"provider.registered": true,
 		"provider.deleted":    true,
 		"provider.updated":    true,
+		"provider.saturated":  true,
+		"provider.recovered":  true,
 
 		// Decision events
 		"decision.created":  true,
@@ -82,9 +134,20 @@ func buildEventFilterSet() map[string]bool {
 		"workflow.started":   true,
 		"workflow.completed": true,
 		"workflow.failed":    true,
+
+		// Golden prompt events
+		"golden_prompts.regression": true,
+
+		// Pull request review events
+		"pr.reviewed": true,
 	}
 }
 
+// Tracks reports whether events of eventType are recorded in the feed
+func (m *Manager) Tracks(eventType string) bool {
+	return m.eventFilterSet[eventType]
+}
+
 // subscribeToEvents subscribes to the event bus
 func (m *Manager) subscribeToEvents() {
 	subscriber := m.eventBus.Subscribe("activity-manager", func(event *eventbus.Event) bool {
@@ -106,60 +169,29 @@ func (m *Manager) RecordActivity(event *eventbus.Event) error {
 		return nil
 	}
 
-	// Check if this event is aggregatable
-	if activity.AggregationKey != "" {
-		m.aggregationMu.Lock()
-		defer m.aggregationMu.Unlock()
-
-		// Check cache first
-		if cached, exists := m.aggregationCache[activity.AggregationKey]; exists {
-			// Check if within time window
-			if time.Since(cached.Timestamp) < aggregationWindow {
-				// Update aggregation count
-				cached.AggregationCount++
-				cached.IsAggregated = true
-
-				// Update in database
-				if err := m.db.UpdateAggregatedActivity(cached.ID, cached.AggregationCount); err != nil {
-					return fmt.Errorf("failed to update aggregated activity: %w", err)
-				}
-
-				// Broadcast updated activity
-				m.broadcastActivity(cached)
-				return nil
-			}
-		}
-
-		// Check database for recent aggregatable activity
-		since := time.Now().Add(-aggregationWindow)
-		existing, err := m.db.GetRecentAggregatableActivity(activity.AggregationKey, since)
-		if err != nil {
-			log.Printf("Failed to check for aggregatable activity: %v", err)
-		}
-
-		if existing != nil {
-			// Update aggregation count
-			existing.AggregationCount++
-
-			if err := m.db.UpdateAggregatedActivity(existing.ID, existing.AggregationCount); err != nil {
-				return fmt.Errorf("failed to update aggregated activity: %w", err)
-			}
+	m.aggregationMu.RLock()
+	rule, ok := m.rules[activity.EventType]
+	m.aggregationMu.RUnlock()
+	if ok && rule.Window > 0 {
+		return m.recordAggregated(activity, rule)
+	}
+	return m.createActivity(activity)
+}
 
-			// Update cache
-			activityFromDB := FromDBActivity(existing)
-			activityFromDB.AggregationCount = existing.AggregationCount
-			m.aggregationCache[activity.AggregationKey] = activityFromDB
+// createActivity stores a new activity and broadcasts it
+func (m *Manager) createActivity(activity *Activity) error {
+	if err := m.storeActivity(activity); err != nil {
+		return err
+	}
 
-			// Broadcast updated activity
-			m.broadcastActivity(activityFromDB)
-			return nil
-		}
+	// Broadcast to subscribers
+	m.broadcastActivity(activity)
 
-		// Mark as aggregatable but first occurrence
-		activity.IsAggregated = true
-		m.aggregationCache[activity.AggregationKey] = activity
-	}
+	return nil
+}
 
+// storeActivity stores a new activity
+func (m *Manager) storeActivity(activity *Activity) error {
 	// Convert metadata to JSON
 	dbActivity := activity.ToDBActivity()
 	if activity.Metadata != nil {
@@ -175,24 +207,25 @@ func (m *Manager) RecordActivity(event *eventbus.Event) error {
 	if err := m.db.CreateActivity(dbActivity); err != nil {
 		return fmt.Errorf("failed to create activity: %w", err)
 	}
-
-	// Broadcast to subscribers
-	m.broadcastActivity(activity)
-
 	return nil
 }
 
 // eventToActivity converts an event to an activity
 func (m *Manager) eventToActivity(event *eventbus.Event) *Activity {
 	activity := &Activity{
-		ID:        uuid.New().String(),
-		EventType: string(event.Type),
-		EventID:   event.ID,
-		Timestamp: event.Timestamp,
-		Source:    event.Source,
-		ProjectID: event.ProjectID,
-		Metadata:  event.Data,
+		ID:               uuid.New().String(),
+		EventType:        string(event.Type),
+		EventID:          event.ID,
+		Timestamp:        event.Timestamp,
+		Source:           event.Source,
+		ProjectID:        event.ProjectID,
+		Metadata:         event.Data,
+		AggregationCount: 1, // The events an aggregated activity stands for
+	}
+	if activity.Timestamp.IsZero() {
+		activity.Timestamp = time.Now()
 	}
+	activity.LastEventAt = activity.Timestamp
 
 	// Extract common fields from event data
 	if actorID, ok := event.Data["actor_id"].(string); ok {
@@ -210,7 +243,10 @@ func (m *Manager) eventToActivity(event *eventbus.Event) *Activity {
 
 	// Extract resource information based on event type
 	switch event.Type {
-	case "bead.created", "bead.assigned", "bead.status_change", "bead.completed":
+	case "bead.created", "bead.assigned", "bead.status_change", "bead.completed", "bead.handed_off", "bead.verification_failed", "bead.ci_status", "bead.guidance_acknowledged",
+		"bead.plan_proposed", "bead.plan_approved", "bead.plan_rejected", "bead.patch_proposed", "bead.patch_approved", "bead.patch_rejected",
+		"bead.undone", "bead.files_locked", "bead.files_unlocked", "bead.file_lock_stolen", "bead.file_lock_deadlock",
+		"bead.model_switched":
 		activity.ResourceType = "bead"
 		if beadID, ok := event.Data["bead_id"].(string); ok {
 			activity.ResourceID = beadID
@@ -221,7 +257,6 @@ func (m *Manager) eventToActivity(event *eventbus.Event) *Activity {
 			activity.ResourceTitle = title
 		}
 		activity.Visibility = "project"
-		activity.AggregationKey = buildAggregationKey(event, activity)
 
 	case "agent.spawned", "agent.status_change", "agent.completed":
 		activity.ResourceType = "agent"
@@ -244,7 +279,7 @@ func (m *Manager) eventToActivity(event *eventbus.Event) *Activity {
 		}
 		activity.Visibility = "global"
 
-	case "provider.registered", "provider.deleted", "provider.updated":
+	case "provider.registered", "provider.deleted", "provider.updated", "provider.saturated", "provider.recovered":
 		activity.ResourceType = "provider"
 		if providerID, ok := event.Data["provider_id"].(string); ok {
 			activity.ResourceID = providerID
@@ -289,11 +324,34 @@ func (m *Manager) eventToActivity(event *eventbus.Event) *Activity {
 		}
 		activity.Visibility = "project"
 
+	case "golden_prompts.regression":
+		activity.ResourceType = "golden_prompt_run"
+		if runID, ok := event.Data["run_id"].(string); ok {
+			activity.ResourceID = runID
+		}
+		activity.Action = extractAction(string(event.Type))
+		if title, ok := event.Data["title"].(string); ok {
+			activity.ResourceTitle = title
+		}
+		activity.Visibility = "project"
+
+	case "pr.reviewed":
+		activity.ResourceType = "pr_review"
+		if reviewID, ok := event.Data["review_id"].(string); ok {
+			activity.ResourceID = reviewID
+		}
+		activity.Action = extractAction(string(event.Type))
+		if title, ok := event.Data["title"].(string); ok {
+			activity.ResourceTitle = title
+		}
+		activity.Visibility = "project"
+
 	default:
 		// Unknown event type, skip
 		return nil
 	}
 
+	activity.OrgID = m.resolveOrg(event, activity)
 	return activity
 }
 
@@ -307,56 +365,39 @@ func extractAction(eventType string) string {
 	return eventType
 }
 
-// buildAggregationKey builds a key for aggregating similar activities
-func buildAggregationKey(event *eventbus.Event, activity *Activity) string {
-	// Only aggregate certain event types
-	switch event.Type {
-	case "bead.created", "agent.spawned":
-		// Format: {event_type}.{date}.{project_id}.{actor_id}
-		date := event.Timestamp.Format("2006-01-02-15") // Group by hour
-		return fmt.Sprintf("%s.%s.%s.%s", event.Type, date, event.ProjectID, activity.ActorID)
-	default:
-		return ""
-	}
-}
-
 // GetActivities retrieves activities with filters
 func (m *Manager) GetActivities(filters ActivityFilters) ([]*Activity, error) {
-	dbFilters := database.ActivityFilters{
-		ProjectIDs:   filters.ProjectIDs,
-		EventType:    filters.EventType,
-		ActorID:      filters.ActorID,
-		ResourceType: filters.ResourceType,
-		Since:        filters.Since,
-		Until:        filters.Until,
-		Limit:        filters.Limit,
-		Offset:       filters.Offset,
-		Aggregated:   filters.Aggregated,
-	}
-
-	dbActivities, err := m.db.ListActivities(dbFilters)
+	dbActivities, err := m.db.ListActivities(filters.toDB())
 	if err != nil {
 		return nil, err
 	}
 
 	activities := make([]*Activity, 0, len(dbActivities))
 	for _, dbActivity := range dbActivities {
-		activity := FromDBActivity(dbActivity)
-
-		// Parse metadata JSON
-		if dbActivity.MetadataJSON != "" {
-			var metadata map[string]interface{}
-			if err := json.Unmarshal([]byte(dbActivity.MetadataJSON), &metadata); err == nil {
-				activity.Metadata = metadata
-			}
-		}
-
-		activities = append(activities, activity)
+		activities = append(activities, fromDBWithMetadata(dbActivity))
 	}
 
 	return activities, nil
 }
 
+// fromDBWithMetadata converts a database activity, parsing its metadata
+func fromDBWithMetadata(dbActivity *database.Activity) *Activity {
+	activity := FromDBActivity(dbActivity)
+	if dbActivity.MetadataJSON != "" {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(dbActivity.MetadataJSON), &metadata); err == nil {
+			activity.Metadata = metadata
+		}
+	}
+	return activity
+}
+
+// CountActivities returns how many activities match filters, ignoring
+// Limit and Offset
+func (m *Manager) CountActivities(filters ActivityFilters) (int, error) {
+	return m.db.CountActivities(filters.toDB())
+}
+
 // Subscribe creates a new activity stream subscriber
 func (m *Manager) Subscribe(subscriberID string) chan *Activity {
 	m.subscribersMu.Lock()
@@ -0,0 +1,331 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+// RetentionConfig sets how long activity feed events and the rollups they
+// are compacted into are kept
+type RetentionConfig struct {
+	RawRetention    time.Duration // Events older than this are archived and compacted; 0 keeps them
+	RollupRetention time.Duration // Rollups whose last event is older than this are deleted; 0 keeps them
+	Interval        time.Duration // Time between compaction runs
+	BatchSize       int           // Events archived and compacted per transaction
+}
+
+// DefaultRetentionConfig keeps raw events for 30 days and rollups for a
+// year, compacting hourly
+func DefaultRetentionConfig() RetentionConfig {
+	return RetentionConfig{
+		RawRetention:    30 * 24 * time.Hour,
+		RollupRetention: 365 * 24 * time.Hour,
+		Interval:        time.Hour,
+		BatchSize:       1000,
+	}
+}
+
+// Archiver keeps the JSONL export of expired events before they are
+// deleted
+type Archiver interface {
+	// Archive stores data, one JSON activity per line, under name
+	Archive(ctx context.Context, name string, data []byte) error
+}
+
+// DirArchiver writes exports as files in a directory
+type DirArchiver string
+
+// Archive implements Archiver
+func (d DirArchiver) Archive(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return err
+	}
+	path := filepath.Join(string(d), name)
+	tmp := path + ".partial"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// RetentionReport is the outcome of a compaction run
+type RetentionReport struct {
+	RanAt          time.Time `json:"ran_at"`
+	Archived       int       `json:"archived"`        // Events exported
+	Compacted      int       `json:"compacted"`       // Events deleted after folding into rollups
+	Rollups        int       `json:"rollups"`         // Rollup rows written or updated
+	RollupsDeleted int64     `json:"rollups_deleted"` // Rollups past their retention
+	Files          []string  `json:"files,omitempty"` // Archive names written
+	DurationMs     int64     `json:"duration_ms"`
+	Error          string    `json:"error,omitempty"`
+}
+
+// Retention archives expired activity feed events, compacts them into
+// rollups and prunes old rollups, on a schedule and on demand. A nil
+// Retention keeps everything.
+type Retention struct {
+	db        *database.Database
+	cfg       RetentionConfig
+	archivers []Archiver
+	now       func() time.Time
+
+	mu      sync.Mutex
+	running bool
+	last    *RetentionReport
+	stop    chan struct{}
+}
+
+// NewRetention returns a Retention over db. Expired events are passed to
+// every archiver before they are deleted.
+func NewRetention(db *database.Database, cfg RetentionConfig, archivers ...Archiver) *Retention {
+	def := DefaultRetentionConfig()
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	return &Retention{db: db, cfg: cfg, archivers: archivers, now: time.Now}
+}
+
+// Config returns the retention settings
+func (r *Retention) Config() RetentionConfig {
+	return r.cfg
+}
+
+// Last returns the report of the latest run, or nil before the first
+func (r *Retention) Last() *RetentionReport {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+// Run archives and compacts the events past RawRetention, then deletes the
+// rollups past RollupRetention. Each batch is archived before it is
+// deleted, so an interrupted run exports a batch again rather than lose it.
+func (r *Retention) Run(ctx context.Context) (*RetentionReport, error) {
+	if r == nil {
+		return nil, fmt.Errorf("activity retention not configured")
+	}
+	r.mu.Lock()
+	if r.running {
+		r.mu.Unlock()
+		return nil, fmt.Errorf("activity retention is already running")
+	}
+	r.running = true
+	r.mu.Unlock()
+
+	start := r.now()
+	report := &RetentionReport{RanAt: start}
+	err := r.run(ctx, report)
+	report.DurationMs = time.Since(start).Milliseconds()
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	r.running = false
+	r.last = report
+	r.mu.Unlock()
+	return report, err
+}
+
+func (r *Retention) run(ctx context.Context, report *RetentionReport) error {
+	now := r.now()
+	if r.cfg.RawRetention > 0 {
+		cutoff := now.Add(-r.cfg.RawRetention)
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			batch, err := r.db.ListExpiredActivities(cutoff, r.cfg.BatchSize)
+			if err != nil {
+				return err
+			}
+			if len(batch) == 0 {
+				break
+			}
+			if len(r.archivers) > 0 {
+				name, err := r.archive(ctx, batch)
+				if err != nil {
+					return err
+				}
+				report.Archived += len(batch)
+				report.Files = append(report.Files, name)
+			}
+
+			rollups := Rollup(batch, now)
+			ids := make([]string, len(batch))
+			for i, a := range batch {
+				ids[i] = a.ID
+			}
+			if err := r.db.CompactActivities(rollups, ids); err != nil {
+				return err
+			}
+			report.Compacted += len(batch)
+			report.Rollups += len(rollups)
+			if len(batch) < r.cfg.BatchSize {
+				break
+			}
+		}
+	}
+
+	if r.cfg.RollupRetention > 0 {
+		n, err := r.db.DeleteActivityRollups(now.Add(-r.cfg.RollupRetention))
+		if err != nil {
+			return err
+		}
+		report.RollupsDeleted = n
+	}
+	return nil
+}
+
+// archive exports a batch as JSONL to every archiver and returns the name
+// it was stored under
+func (r *Retention) archive(ctx context.Context, batch []*database.Activity) (string, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, a := range batch {
+		act := FromDBActivity(a)
+		if a.MetadataJSON != "" {
+			if err := json.Unmarshal([]byte(a.MetadataJSON), &act.Metadata); err != nil {
+				log.Printf("[Activity] Archiving activity %s without its unreadable metadata: %v", a.ID, err)
+			}
+		}
+		if err := enc.Encode(act); err != nil {
+			return "", fmt.Errorf("failed to encode activity %s: %w", a.ID, err)
+		}
+	}
+
+	first := batch[0].Timestamp.UTC()
+	name := fmt.Sprintf("activity-%s-%s.jsonl", first.Format("20060102T150405Z"), uuid.New().String()[:8])
+	for _, arc := range r.archivers {
+		if err := arc.Archive(ctx, name, buf.Bytes()); err != nil {
+			return "", fmt.Errorf("failed to archive expired activities: %w", err)
+		}
+	}
+	return name, nil
+}
+
+// Rollup collapses activities into summary rows. Activities sharing an
+// aggregation key collapse into one row for the hour the key covers;
+// others collapse by event type, resource type, project and actor per day.
+// Each row counts the events its activities stand for.
+func Rollup(activities []*database.Activity, now time.Time) []*database.ActivityRollup {
+	byKey := make(map[string]*database.ActivityRollup)
+	var rollups []*database.ActivityRollup
+	for _, a := range activities {
+		ts := a.Timestamp.UTC()
+		var key string
+		var period time.Time
+		if a.AggregationKey != "" {
+			period = ts.Truncate(time.Hour)
+			key = strings.Join([]string{a.OrgID, a.AggregationKey}, "|")
+		} else {
+			period = time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+			key = strings.Join([]string{a.OrgID, a.EventType, a.ResourceType, a.ProjectID, a.ActorID, period.Format("2006-01-02")}, "|")
+		}
+
+		count := a.AggregationCount
+		if count < 1 {
+			count = 1
+		}
+		r, ok := byKey[key]
+		if !ok {
+			r = &database.ActivityRollup{
+				Key:            key,
+				OrgID:          a.OrgID,
+				EventType:      a.EventType,
+				ProjectID:      a.ProjectID,
+				ActorID:        a.ActorID,
+				ResourceType:   a.ResourceType,
+				AggregationKey: a.AggregationKey,
+				PeriodStart:    period,
+				FirstAt:        a.Timestamp,
+				LastAt:         a.Timestamp,
+				UpdatedAt:      now,
+			}
+			byKey[key] = r
+			rollups = append(rollups, r)
+		}
+		r.EventCount += count
+		if a.Timestamp.Before(r.FirstAt) {
+			r.FirstAt = a.Timestamp
+		}
+		if a.Timestamp.After(r.LastAt) {
+			r.LastAt = a.Timestamp
+		}
+	}
+	return rollups
+}
+
+// Rollups returns the stored rollups matching filters
+func (r *Retention) Rollups(filters database.ActivityRollupFilters) ([]*database.ActivityRollup, error) {
+	if r == nil {
+		return nil, fmt.Errorf("activity retention not configured")
+	}
+	return r.db.ListActivityRollups(filters)
+}
+
+// Start runs compaction every Interval until ctx is done or Close is
+// called
+func (r *Retention) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.stop != nil {
+		r.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	r.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if report, err := r.Run(ctx); err != nil {
+				log.Printf("[Activity] Retention run failed: %v", err)
+			} else if report.Compacted > 0 || report.RollupsDeleted > 0 {
+				log.Printf("[Activity] Compacted %d expired activities into %d rollups, archived %d, deleted %d old rollups",
+					report.Compacted, report.Rollups, report.Archived, report.RollupsDeleted)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops scheduled compaction
+func (r *Retention) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
@@ -27,20 +27,48 @@ type Activity struct {
 	AggregationKey   string                 `json:"aggregation_key,omitempty"`
 	AggregationCount int                    `json:"aggregation_count"`
 	IsAggregated     bool                   `json:"is_aggregated"`
+	LastEventAt      time.Time              `json:"last_event_at"`     // Latest event an aggregated activity stands for
+	Summary          string                 `json:"summary,omitempty"` // Describes an aggregated activity, such as "agent-3 created 42 beads in 5 minutes"
 	Visibility       string                 `json:"visibility"`
+	OrgID            string                 `json:"org_id"`
 }
 
 // ActivityFilters defines filters for querying activities
 type ActivityFilters struct {
+	OrgID        string // Only this organization's activities; empty for all
 	ProjectIDs   []string
 	EventType    string
+	EventTypes   []string // Any of these event types
 	ActorID      string
+	ActorIDs     []string // Any of these actors
 	ResourceType string
 	Since        time.Time
 	Until        time.Time
 	Limit        int
 	Offset       int
+	After        []interface{} // (timestamp, id) of the last activity of the previous page
 	Aggregated   *bool
+	Ascending    bool // oldest first; newest first by default
+}
+
+// toDB converts the filters to their database form
+func (f ActivityFilters) toDB() database.ActivityFilters {
+	return database.ActivityFilters{
+		OrgID:        f.OrgID,
+		ProjectIDs:   f.ProjectIDs,
+		EventType:    f.EventType,
+		EventTypes:   f.EventTypes,
+		ActorID:      f.ActorID,
+		ActorIDs:     f.ActorIDs,
+		ResourceType: f.ResourceType,
+		Since:        f.Since,
+		Until:        f.Until,
+		Limit:        f.Limit,
+		Offset:       f.Offset,
+		After:        f.After,
+		Aggregated:   f.Aggregated,
+		Ascending:    f.Ascending,
+	}
 }
 
 // ToDBActivity converts Activity to database.Activity
@@ -65,12 +93,14 @@ func (a *Activity) ToDBActivity() *database.Activity {
 		AggregationCount: a.AggregationCount,
 		IsAggregated:     a.IsAggregated,
 		Visibility:       a.Visibility,
+		OrgID:            a.OrgID,
+		LastEventAt:      a.LastEventAt,
 	}
 }
 
 // FromDBActivity converts database.Activity to Activity
 func FromDBActivity(dbActivity *database.Activity) *Activity {
-	return &Activity{
+	a := &Activity{
 		ID:               dbActivity.ID,
 		EventType:        dbActivity.EventType,
 		EventID:          dbActivity.EventID,
@@ -89,6 +119,10 @@ func FromDBActivity(dbActivity *database.Activity) *Activity {
 		AggregationKey:   dbActivity.AggregationKey,
 		AggregationCount: dbActivity.AggregationCount,
 		IsAggregated:     dbActivity.IsAggregated,
+		LastEventAt:      dbActivity.LastEventAt,
 		Visibility:       dbActivity.Visibility,
+		OrgID:            dbActivity.OrgID,
 	}
+	a.Summary = summarize(a)
+	return a
 }
@@ -0,0 +1,173 @@
+package activity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+// View is a named set of activity feed filters a user saves, such as "my
+// projects' bead events". A shared view may be used, and subscribed to, by
+// everyone in its owner's organization; only its owner may change it.
+type View struct {
+	ID        string      `json:"id"`
+	OrgID     string      `json:"org_id"`
+	OwnerID   string      `json:"owner_id"`
+	Name      string      `json:"name"`
+	Shared    bool        `json:"shared"`
+	Filters   ViewFilters `json:"filters"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// ViewFilters selects the activities of a view. Empty fields match
+// everything.
+type ViewFilters struct {
+	ProjectIDs   []string `json:"project_ids,omitempty"`
+	EventTypes   []string `json:"event_types,omitempty"`
+	ActorIDs     []string `json:"actor_ids,omitempty"`
+	ResourceType string   `json:"resource_type,omitempty"`
+}
+
+// Apply narrows filters to the activities of the view
+func (f ViewFilters) Apply(filters *ActivityFilters) {
+	if len(f.ProjectIDs) > 0 {
+		filters.ProjectIDs = f.ProjectIDs
+	}
+	if len(f.EventTypes) > 0 {
+		filters.EventTypes = f.EventTypes
+	}
+	if len(f.ActorIDs) > 0 {
+		filters.ActorIDs = f.ActorIDs
+	}
+	if f.ResourceType != "" {
+		filters.ResourceType = f.ResourceType
+	}
+}
+
+// Matches reports whether a is one of the view's activities. Like the
+// feed, a view of projects includes global activities.
+func (f ViewFilters) Matches(a *Activity) bool {
+	if len(f.ProjectIDs) > 0 && a.Visibility != "global" && !contains(f.ProjectIDs, a.ProjectID) {
+		return false
+	}
+	if len(f.EventTypes) > 0 && !contains(f.EventTypes, a.EventType) {
+		return false
+	}
+	if len(f.ActorIDs) > 0 && !contains(f.ActorIDs, a.ActorID) {
+		return false
+	}
+	return f.ResourceType == "" || f.ResourceType == a.ResourceType
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// VisibleTo reports whether a user may use the view
+func (v *View) VisibleTo(userID string) bool {
+	return v.Shared || v.OwnerID == userID
+}
+
+// ErrInvalidView is returned for views that cannot be saved
+var ErrInvalidView = errors.New("invalid activity view")
+
+// ToDBView converts View to database.ActivityView
+func (v *View) ToDBView() (*database.ActivityView, error) {
+	filters, err := json.Marshal(v.Filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal view filters: %w", err)
+	}
+	return &database.ActivityView{
+		ID:          v.ID,
+		OrgID:       v.OrgID,
+		OwnerID:     v.OwnerID,
+		Name:        v.Name,
+		Shared:      v.Shared,
+		FiltersJSON: string(filters),
+		CreatedAt:   v.CreatedAt,
+		UpdatedAt:   v.UpdatedAt,
+	}, nil
+}
+
+// FromDBView converts database.ActivityView to View
+func FromDBView(dbView *database.ActivityView) *View {
+	v := &View{
+		ID:        dbView.ID,
+		OrgID:     dbView.OrgID,
+		OwnerID:   dbView.OwnerID,
+		Name:      dbView.Name,
+		Shared:    dbView.Shared,
+		CreatedAt: dbView.CreatedAt,
+		UpdatedAt: dbView.UpdatedAt,
+	}
+	if dbView.FiltersJSON != "" {
+		_ = json.Unmarshal([]byte(dbView.FiltersJSON), &v.Filters)
+	}
+	return v
+}
+
+// CreateView saves a new view owned by v.OwnerID
+func (m *Manager) CreateView(v *View) error {
+	if v.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidView)
+	}
+	v.ID = uuid.New().String()
+	v.CreatedAt = time.Now().UTC()
+	v.UpdatedAt = v.CreatedAt
+	dbView, err := v.ToDBView()
+	if err != nil {
+		return err
+	}
+	return m.db.CreateActivityView(dbView)
+}
+
+// UpdateView saves the name, sharing and filters of a view
+func (m *Manager) UpdateView(v *View) error {
+	if v.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidView)
+	}
+	v.UpdatedAt = time.Now().UTC()
+	dbView, err := v.ToDBView()
+	if err != nil {
+		return err
+	}
+	return m.db.UpdateActivityView(dbView)
+}
+
+// GetView returns a view, or nil when there is none
+func (m *Manager) GetView(id string) (*View, error) {
+	dbView, err := m.db.GetActivityView(id)
+	if err != nil || dbView == nil {
+		return nil, err
+	}
+	return FromDBView(dbView), nil
+}
+
+// ListViews returns the views of an organization a user may use: their
+// own and those shared with them, by name
+func (m *Manager) ListViews(orgID, userID string) ([]*View, error) {
+	dbViews, err := m.db.ListActivityViews(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	views := make([]*View, 0, len(dbViews))
+	for _, dbView := range dbViews {
+		views = append(views, FromDBView(dbView))
+	}
+	return views, nil
+}
+
+// DeleteView deletes a view and the subscriptions to it
+func (m *Manager) DeleteView(id string) error {
+	return m.db.DeleteActivityView(id)
+}
@@ -0,0 +1,73 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+)
+
+func TestViews_SharingAndFilters(t *testing.T) {
+	m := newTestManager(t)
+	mine := &View{OrgID: "default", OwnerID: "alice", Name: "Mine", Filters: ViewFilters{ActorIDs: []string{"agent-3"}}}
+	shared := &View{OrgID: "default", OwnerID: "bob", Name: "Team", Shared: true, Filters: ViewFilters{EventTypes: []string{"bead.completed"}}}
+	private := &View{OrgID: "default", OwnerID: "bob", Name: "Bob's"}
+	other := &View{OrgID: "acme", OwnerID: "alice", Name: "Elsewhere", Shared: true}
+	for _, v := range []*View{mine, shared, private, other} {
+		if err := m.CreateView(v); err != nil {
+			t.Fatalf("CreateView failed: %v", err)
+		}
+	}
+	if err := m.CreateView(&View{OrgID: "default", OwnerID: "alice"}); err == nil {
+		t.Error("expected a view without a name to be refused")
+	}
+
+	views, err := m.ListViews("default", "alice")
+	if err != nil {
+		t.Fatalf("ListViews failed: %v", err)
+	}
+	if len(views) != 2 || views[0].ID != mine.ID || views[1].ID != shared.ID {
+		t.Fatalf("ListViews = %+v", views)
+	}
+	if views[0].Filters.ActorIDs[0] != "agent-3" {
+		t.Errorf("filters not kept: %+v", views[0].Filters)
+	}
+
+	// Beads created and completed by agent-3 and agent-4
+	m.SetAggregationRules(nil)
+	for _, e := range []struct{ actor, eventType string }{
+		{"agent-3", "bead.created"}, {"agent-3", "bead.completed"}, {"agent-4", "bead.completed"},
+	} {
+		event := beadCreated(e.actor+e.eventType, e.actor, time.Now())
+		event.Type = eventbus.EventType(e.eventType)
+		if err := m.RecordActivity(event); err != nil {
+			t.Fatalf("RecordActivity failed: %v", err)
+		}
+	}
+	for _, tt := range []struct {
+		view *View
+		want int
+	}{{mine, 2}, {shared, 2}} {
+		var filters ActivityFilters
+		tt.view.Filters.Apply(&filters)
+		activities, err := m.GetActivities(filters)
+		if err != nil {
+			t.Fatalf("GetActivities failed: %v", err)
+		}
+		if len(activities) != tt.want {
+			t.Errorf("view %s: %d activities, want %d", tt.view.Name, len(activities), tt.want)
+		}
+		for _, a := range activities {
+			if !tt.view.Filters.Matches(a) {
+				t.Errorf("view %s listed %+v, which it does not match", tt.view.Name, a)
+			}
+		}
+	}
+
+	if err := m.DeleteView(mine.ID); err != nil {
+		t.Fatalf("DeleteView failed: %v", err)
+	}
+	if v, err := m.GetView(mine.ID); err != nil || v != nil {
+		t.Errorf("GetView after delete = %+v, %v", v, err)
+	}
+}
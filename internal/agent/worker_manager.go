@@ -10,6 +10,7 @@ import (
 
 	"github.com/jordanhubbard/loom/internal/actions"
 	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/artifacts"
 	"github.com/jordanhubbard/loom/internal/database"
 	"github.com/jordanhubbard/loom/internal/observability"
 	"github.com/jordanhubbard/loom/internal/provider"
@@ -29,7 +30,14 @@ type WorkerManager struct {
 	analyticsLogger    *analytics.Logger
 	actionLoopEnabled  bool
 	maxLoopIterations  int
+	maxLoopResumes     int
 	lessonsProvider    worker.LessonsProvider
+	fileExpertise      worker.FileExpertiseProvider
+	guidance           worker.GuidanceProvider
+	repairer           worker.ResponseRepairer
+	modelSwitcher      worker.ModelSwitcher
+	downgradeAfter     int
+	artifactRecorder   *artifacts.Recorder
 	db                 *database.Database
 	mu                 sync.RWMutex
 	maxAgents          int
@@ -64,6 +72,15 @@ func (m *WorkerManager) SetAnalyticsLogger(l *analytics.Logger) {
 	m.analyticsLogger = l
 }
 
+// estimateCost prices tokens used by an agent on its provider, so analytics
+// can attribute spend to projects, beads and dispatches
+func (m *WorkerManager) estimateCost(providerID, model string, tokens int) float64 {
+	if m.providerRegistry == nil || tokens <= 0 {
+		return 0
+	}
+	return m.providerRegistry.EstimateCost(providerID, model, tokens, 0)
+}
+
 func (m *WorkerManager) SetActionLoopEnabled(enabled bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -76,12 +93,75 @@ func (m *WorkerManager) SetMaxLoopIterations(max int) {
 	m.maxLoopIterations = max
 }
 
+func (m *WorkerManager) SetMaxLoopResumes(max int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxLoopResumes = max
+}
+
+// SetMaxAgents changes how many agents and workers may run at once. Agents
+// already running beyond a lowered limit keep running.
+func (m *WorkerManager) SetMaxAgents(max int) {
+	if max <= 0 {
+		return
+	}
+	m.mu.Lock()
+	m.maxAgents = max
+	m.mu.Unlock()
+	m.workerPool.SetMaxWorkers(max)
+}
+
 func (m *WorkerManager) SetLessonsProvider(lp worker.LessonsProvider) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.lessonsProvider = lp
 }
 
+func (m *WorkerManager) SetFileExpertiseProvider(fp worker.FileExpertiseProvider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fileExpertise = fp
+}
+
+// SetGuidanceProvider passes comments humans post on a bead to the agent
+// working on it
+func (m *WorkerManager) SetGuidanceProvider(gp worker.GuidanceProvider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.guidance = gp
+}
+
+// SetResponseRepairer has action loop responses that do not parse
+// repaired before the agent is told they failed
+func (m *WorkerManager) SetResponseRepairer(r worker.ResponseRepairer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.repairer = r
+}
+
+// SetModelSwitcher lets action loops move to a stronger model when they
+// struggle and a cheaper one after downgradeAfter simple iterations
+// (0 = worker.DefaultDowngradeAfter, negative = never)
+func (m *WorkerManager) SetModelSwitcher(s worker.ModelSwitcher, downgradeAfter int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.modelSwitcher = s
+	m.downgradeAfter = downgradeAfter
+}
+
+// SetArtifactRecorder records each action loop's prompts, responses and
+// test logs as run artifacts
+func (m *WorkerManager) SetArtifactRecorder(r *artifacts.Recorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.artifactRecorder = r
+}
+
+// SetContextBudget sets how workers size their prompts for each model
+func (m *WorkerManager) SetContextBudget(b *worker.ContextBudget) {
+	m.workerPool.SetContextBudget(b)
+}
+
 func (m *WorkerManager) SetDatabase(db *database.Database) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -430,9 +510,22 @@ func (m *WorkerManager) ExecuteTask(ctx context.Context, agentID string, task *w
 			}
 			m.mu.Unlock()
 		}
+		// The files the task locked are free once it ends
+		if router := m.actionRouter; router != nil && router.Locks != nil && task != nil {
+			router.Locks.ReleaseFiles(context.Background(), actions.ActionContext{AgentID: agentID, BeadID: task.BeadID, ProjectID: task.ProjectID})
+		}
 		_ = m.UpdateAgentStatus(agentID, "idle")
 	}()
 
+	// A worker bound to a different provider than the agent's current one
+	// was spawned before the dispatcher re-routed the agent (by complexity or
+	// project compliance constraints); replace it so the task runs on the
+	// provider it was routed to.
+	if w, workerErr := m.workerPool.GetWorker(agentID); workerErr == nil && agent.ProviderID != "" && w.GetInfo().ProviderID != agent.ProviderID {
+		log.Printf("[WorkerManager] Rebinding worker for %s from provider %s to %s", agentID, w.GetInfo().ProviderID, agent.ProviderID)
+		_ = m.workerPool.StopWorker(agentID)
+	}
+
 	// Ensure a worker exists for this agent; auto-spawn if the agent has a
 	// provider but no worker yet (e.g. agents created without a provider that
 	// were later auto-assigned one by the dispatcher).
@@ -469,8 +562,15 @@ func (m *WorkerManager) ExecuteTask(ctx context.Context, agentID string, task *w
 				ProjectID: task.ProjectID,
 			},
 			LessonsProvider: m.lessonsProvider,
+			FileExpertise:   m.fileExpertise,
 			DB:              m.db,
+			Artifacts:       m.artifactRecorder,
+			Guidance:        m.guidance,
 			TextMode:        true, // Default to simple text actions for local model effectiveness
+			MaxResumes:      m.maxLoopResumes,
+			Repairer:        m.repairer,
+			ModelSwitcher:   m.modelSwitcher,
+			DowngradeAfter:  m.downgradeAfter,
 		}
 
 		loopResult, loopErr := workerInstance.ExecuteTaskWithLoop(ctx, task, loopConfig)
@@ -536,11 +636,14 @@ func (m *WorkerManager) ExecuteTask(ctx context.Context, agentID string, task *w
 				TotalTokens: int64(result.TokensUsed),
 				LatencyMs:   elapsed.Milliseconds(),
 				StatusCode:  statusCode,
+				CostUSD:     m.estimateCost(agent.ProviderID, "", result.TokensUsed),
 				ErrorMessage: result.Error,
 				Metadata: map[string]string{
 					"agent_id":        agent.ID,
+					"project_id":      projectID,
 					"bead_id":         beadID,
 					"task_id":         taskID,
+					"persona":         agent.PersonaName,
 					"loop_iterations": fmt.Sprintf("%d", loopResult.Iterations),
 					"terminal_reason": loopResult.TerminalReason,
 				},
@@ -573,9 +676,11 @@ func (m *WorkerManager) ExecuteTask(ctx context.Context, agentID string, task *w
 				StatusCode: 500,
 				ErrorMessage: err.Error(),
 				Metadata: map[string]string{
-					"agent_id": agent.ID,
-					"bead_id":  beadID,
-					"task_id":  taskID,
+					"agent_id":   agent.ID,
+					"project_id": projectID,
+					"bead_id":    beadID,
+					"task_id":    taskID,
+					"persona":    agent.PersonaName,
 				},
 			})
 		}
@@ -655,11 +760,14 @@ func (m *WorkerManager) ExecuteTask(ctx context.Context, agentID string, task *w
 			TotalTokens:      int64(result.TokensUsed),
 			LatencyMs:        elapsed.Milliseconds(),
 			StatusCode:       statusCode,
+			CostUSD:          m.estimateCost(agent.ProviderID, modelName, result.TokensUsed),
 			ErrorMessage:     result.Error,
 			Metadata: map[string]string{
-				"agent_id": agent.ID,
-				"bead_id":  beadID,
-				"task_id":  taskID,
+				"agent_id":   agent.ID,
+				"project_id": projectID,
+				"bead_id":    beadID,
+				"task_id":    taskID,
+				"persona":    agent.PersonaName,
 			},
 		})
 	}
@@ -768,6 +876,23 @@ func (m *WorkerManager) UpdateAgentStatus(id, status string) error {
 	return nil
 }
 
+// SetAgentPersona gives every agent of a persona its newly published
+// definition, which their next task's system prompt uses. It returns how
+// many agents were updated.
+func (m *WorkerManager) SetAgentPersona(personaName string, persona *models.Persona) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	updated := 0
+	for _, agent := range m.agents {
+		if agent.PersonaName == personaName {
+			agent.Persona = persona
+			updated++
+		}
+	}
+	return updated
+}
+
 // AssignBead assigns a bead to an agent
 func (m *WorkerManager) AssignBead(agentID, beadID string) error {
 	m.mu.Lock()
@@ -47,6 +47,7 @@ type Alert struct {
 	Threshold    float64   `json:"threshold"`
 	TriggeredAt  time.Time `json:"triggered_at"`
 	Acknowledged bool      `json:"acknowledged"`
+	Drilldown    string    `json:"drilldown,omitempty"` // API path decomposing an anomaly by contributor
 }
 
 // AlertChecker monitors spending and triggers alerts
@@ -193,22 +194,21 @@ func (ac *AlertChecker) checkAnomalies(ctx context.Context) *Alert {
 	now := time.Now()
 
 	// Get today's spending
-	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	today, history := AnomalyWindow(now)
 	todayStats, err := ac.storage.GetLogStats(ctx, &LogFilter{
 		UserID:    ac.config.UserID,
-		StartTime: startOfToday,
-		EndTime:   now,
+		StartTime: today.Start,
+		EndTime:   today.End,
 	})
 	if err != nil {
 		return nil
 	}
 
 	// Get average spending from last 7 days (excluding today)
-	sevenDaysAgo := startOfToday.Add(-7 * 24 * time.Hour)
 	historicalStats, err := ac.storage.GetLogStats(ctx, &LogFilter{
 		UserID:    ac.config.UserID,
-		StartTime: sevenDaysAgo,
-		EndTime:   startOfToday,
+		StartTime: history.Start,
+		EndTime:   history.End,
 	})
 	if err != nil {
 		return nil
@@ -228,6 +228,7 @@ func (ac *AlertChecker) checkAnomalies(ctx context.Context) *Alert {
 			CurrentCost: todayStats.TotalCostUSD,
 			Threshold:   avgDailySpend * ac.config.AnomalyThreshold,
 			TriggeredAt: now,
+			Drilldown:   DrilldownPath(today, history),
 		}
 	}
 
@@ -275,6 +276,9 @@ func (ac *AlertChecker) sendWebhook(alert *Alert) error {
 		"threshold":    alert.Threshold,
 		"triggered_at": alert.TriggeredAt.Format(time.RFC3339),
 	}
+	if alert.Drilldown != "" {
+		payload["drilldown"] = alert.Drilldown
+	}
 
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
@@ -318,10 +322,7 @@ func (ac *AlertChecker) sendEmail(alert *Alert) error {
 	}
 
 	// Determine sender email
-	from := ac.smtpConfig.From
-	if from == "" {
-		from = ac.smtpConfig.Username // Fallback to username if From not set
-	}
+	from := ac.smtpConfig.Sender()
 
 	// Build email message
 	subject := fmt.Sprintf("[Loom Alert] %s: %s", alert.Severity, alert.Type)
@@ -342,19 +343,38 @@ func (ac *AlertChecker) sendEmail(alert *Alert) error {
 		body,
 	))
 
+	return ac.smtpConfig.SendMail([]string{ac.config.EmailAddress}, message)
+}
+
+// SMTPConfigFromEnv returns the SMTP configuration from the SMTP_*
+// environment variables, or nil when SMTP_HOST is unset
+func SMTPConfigFromEnv() *SMTPConfig {
+	return loadSMTPConfigFromEnv()
+}
+
+// Sender returns the address mail is sent from
+func (c *SMTPConfig) Sender() string {
+	if c.From != "" {
+		return c.From
+	}
+	return c.Username // Fallback to username if From not set
+}
+
+// SendMail sends a complete message, headers included, to recipients
+func (c *SMTPConfig) SendMail(to []string, message []byte) error {
 	// Set up authentication
-	auth := smtp.PlainAuth("", ac.smtpConfig.Username, ac.smtpConfig.Password, ac.smtpConfig.Host)
+	auth := smtp.PlainAuth("", c.Username, c.Password, c.Host)
 
 	// Send email
-	addr := fmt.Sprintf("%s:%d", ac.smtpConfig.Host, ac.smtpConfig.Port)
+	addr := fmt.Sprintf("%s:%d", c.Host, c.Port)
 
-	if ac.smtpConfig.UseTLS {
+	if c.UseTLS {
 		// Use TLS (recommended for most SMTP servers)
-		return sendEmailTLS(addr, auth, from, []string{ac.config.EmailAddress}, message, ac.smtpConfig.Host)
+		return sendEmailTLS(addr, auth, c.Sender(), to, message, c.Host)
 	}
 
 	// Send without TLS (not recommended for production)
-	return smtp.SendMail(addr, auth, from, []string{ac.config.EmailAddress}, message)
+	return smtp.SendMail(addr, auth, c.Sender(), to, message)
 }
 
 // sendEmailTLS sends email using explicit TLS
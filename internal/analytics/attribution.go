@@ -0,0 +1,106 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// MetadataLoopIterations is the number of action loop iterations a logged
+// dispatch ran
+const MetadataLoopIterations = "loop_iterations"
+
+// BeadCost is what the requests made for one bead cost, joined with the
+// commits and pull requests they produced
+type BeadCost struct {
+	BeadID         string             `json:"bead_id"`
+	ProjectID      string             `json:"project_id,omitempty"`
+	CostUSD        float64            `json:"cost_usd"`
+	Tokens         int64              `json:"tokens"`
+	Requests       int64              `json:"requests"`
+	Dispatches     int                `json:"dispatches"`
+	Iterations     int                `json:"iterations"` // Action loop iterations over all dispatches
+	FirstRequestAt time.Time          `json:"first_request_at,omitempty"`
+	LastRequestAt  time.Time          `json:"last_request_at,omitempty"`
+	Commits        []string           `json:"commits,omitempty"` // SHAs of the commits made for the bead
+	PullRequests   []*PullRequestCost `json:"pull_requests,omitempty"`
+}
+
+// PullRequestCost is what a bead had cost when one of its pull requests was
+// opened
+type PullRequestCost struct {
+	Number   int       `json:"number"`
+	URL      string    `json:"url,omitempty"`
+	OpenedAt time.Time `json:"opened_at"`
+	CostUSD  float64   `json:"cost_usd"`
+	Tokens   int64     `json:"tokens"`
+}
+
+// Trailer formats the cost as trailers for a pull request body or commit
+// message
+func (c *BeadCost) Trailer() string {
+	return fmt.Sprintf("Cost: $%.4f\nTokens: %d\nIterations: %d", c.CostUSD, c.Tokens, c.Iterations)
+}
+
+// AttributeCosts groups logs by the bead they were made for, leaving out
+// requests made for no bead. The result is ordered by cost, highest first.
+func AttributeCosts(logs []*RequestLog) []*BeadCost {
+	byBead := make(map[string]*BeadCost)
+	dispatches := make(map[string]map[string]bool)
+	for _, l := range logs {
+		id := l.Metadata[MetadataBeadID]
+		if id == "" {
+			continue
+		}
+		c, ok := byBead[id]
+		if !ok {
+			c = &BeadCost{BeadID: id, FirstRequestAt: l.Timestamp, LastRequestAt: l.Timestamp}
+			byBead[id] = c
+			dispatches[id] = make(map[string]bool)
+		}
+		if c.ProjectID == "" {
+			c.ProjectID = l.Metadata[MetadataProjectID]
+		}
+		c.CostUSD += l.CostUSD
+		c.Tokens += l.TotalTokens
+		c.Requests++
+		if n, err := strconv.Atoi(l.Metadata[MetadataLoopIterations]); err == nil {
+			c.Iterations += n
+		}
+		if task := l.Metadata[MetadataTaskID]; task != "" {
+			dispatches[id][task] = true
+		}
+		if l.Timestamp.Before(c.FirstRequestAt) {
+			c.FirstRequestAt = l.Timestamp
+		}
+		if l.Timestamp.After(c.LastRequestAt) {
+			c.LastRequestAt = l.Timestamp
+		}
+	}
+
+	list := make([]*BeadCost, 0, len(byBead))
+	for id, c := range byBead {
+		c.Dispatches = len(dispatches[id])
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CostUSD != list[j].CostUSD {
+			return list[i].CostUSD > list[j].CostUSD
+		}
+		return list[i].BeadID < list[j].BeadID
+	})
+	return list
+}
+
+// CostUntil sums the cost and tokens of the requests made for beadID up to
+// and including t
+func CostUntil(logs []*RequestLog, beadID string, t time.Time) (costUSD float64, tokens int64) {
+	for _, l := range logs {
+		if l.Metadata[MetadataBeadID] == beadID && !l.Timestamp.After(t) {
+			costUSD += l.CostUSD
+			tokens += l.TotalTokens
+		}
+	}
+	return costUSD, tokens
+}
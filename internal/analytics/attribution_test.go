@@ -0,0 +1,52 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttributeCosts(t *testing.T) {
+	start := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	log := func(minutes int, cost float64, bead, task, iterations string) *RequestLog {
+		return &RequestLog{
+			Timestamp:   start.Add(time.Duration(minutes) * time.Minute),
+			CostUSD:     cost,
+			TotalTokens: 100,
+			Metadata: map[string]string{
+				MetadataProjectID:      "proj",
+				MetadataBeadID:         bead,
+				MetadataTaskID:         task,
+				MetadataLoopIterations: iterations,
+			},
+		}
+	}
+	logs := []*RequestLog{
+		log(30, 0.50, "bead-1", "task-2", "3"),
+		log(0, 1.00, "bead-1", "task-1", "5"),
+		log(10, 2.00, "bead-2", "task-3", ""),
+		log(20, 9.00, "", "", ""),
+	}
+
+	costs := AttributeCosts(logs)
+	if len(costs) != 2 {
+		t.Fatalf("expected 2 beads, got %d", len(costs))
+	}
+	if costs[0].BeadID != "bead-2" {
+		t.Errorf("expected the costliest bead first, got %s", costs[0].BeadID)
+	}
+	b := costs[1]
+	if b.CostUSD != 1.50 || b.Tokens != 200 || b.Requests != 2 || b.Dispatches != 2 || b.Iterations != 8 || b.ProjectID != "proj" {
+		t.Errorf("unexpected bead-1 totals: %+v", b)
+	}
+	if !b.FirstRequestAt.Equal(start) || !b.LastRequestAt.Equal(start.Add(30*time.Minute)) {
+		t.Errorf("unexpected request span %s - %s", b.FirstRequestAt, b.LastRequestAt)
+	}
+	if got := b.Trailer(); got != "Cost: $1.5000\nTokens: 200\nIterations: 8" {
+		t.Errorf("unexpected trailer %q", got)
+	}
+
+	cost, tokens := CostUntil(logs, "bead-1", start.Add(15*time.Minute))
+	if cost != 1.00 || tokens != 100 {
+		t.Errorf("expected only the first request before the PR, got %.2f / %d", cost, tokens)
+	}
+}
@@ -0,0 +1,41 @@
+package analytics
+
+import (
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// MetadataProjectID is the RequestLog metadata key naming the project a
+// request was made for; it selects the project's compliance constraints
+const MetadataProjectID = "project_id"
+
+// ComplianceLookup returns a project's compliance constraints, or nil
+type ComplianceLookup func(projectID string) *models.ComplianceConstraints
+
+// SetComplianceLookup makes the logger apply per-project compliance
+// constraints on top of its privacy config
+func (l *Logger) SetComplianceLookup(lookup ComplianceLookup) {
+	l.compliance = lookup
+}
+
+// privacyFor returns the privacy config for a log entry, tightened by the
+// compliance constraints of the entry's project. Constraints can only make
+// logging stricter, never looser.
+func (l *Logger) privacyFor(log *RequestLog) (*PrivacyConfig, *models.ComplianceConstraints) {
+	if l.compliance == nil || log.Metadata == nil || log.Metadata[MetadataProjectID] == "" {
+		return l.privacy, nil
+	}
+	c := l.compliance(log.Metadata[MetadataProjectID])
+	if !c.Enabled() {
+		return l.privacy, nil
+	}
+
+	privacy := *l.privacy
+	if c.NoBodyLogging {
+		privacy.LogRequestBodies = false
+		privacy.LogResponseBodies = false
+	}
+	if c.RequireRedaction && len(privacy.RedactPatterns) == 0 {
+		privacy.RedactPatterns = DefaultPrivacyConfig().RedactPatterns
+	}
+	return &privacy, c
+}
@@ -342,3 +342,40 @@ func (s *InMemoryStorage) DeleteOldLogs(ctx context.Context, before time.Time) (
 	s.logs = newLogs
 	return deleted, nil
 }
+
+// flatPricer prices one model at $1 per million prompt tokens and $2 per
+// million completion tokens
+type flatPricer struct{ model string }
+
+func (p flatPricer) Cost(providerID, model string, promptTokens, completionTokens, cachedTokens int64) (float64, bool) {
+	if model != p.model {
+		return 0, false
+	}
+	return (float64(promptTokens) + 2*float64(completionTokens)) / 1e6, true
+}
+
+func TestLogger_PricesKnownModels(t *testing.T) {
+	storage := NewInMemoryStorage()
+	logger := NewLogger(storage, nil)
+	logger.SetPricer(flatPricer{model: "priced"})
+	ctx := context.Background()
+
+	priced := &RequestLog{ModelName: "priced", PromptTokens: 1000000, CompletionTokens: 500000, CostUSD: 99}
+	unpriced := &RequestLog{ModelName: "other", TotalTokens: 1000, CostUSD: 0.5}
+	totalOnly := &RequestLog{ModelName: "priced", TotalTokens: 2000000}
+	for _, l := range []*RequestLog{priced, unpriced, totalOnly} {
+		if err := logger.LogRequest(ctx, l); err != nil {
+			t.Fatalf("LogRequest failed: %v", err)
+		}
+	}
+
+	if priced.CostUSD != 2 {
+		t.Errorf("expected the catalog price over the logged cost, got %v", priced.CostUSD)
+	}
+	if unpriced.CostUSD != 0.5 {
+		t.Errorf("expected an unpriced model to keep its logged cost, got %v", unpriced.CostUSD)
+	}
+	if totalOnly.CostUSD != 2 {
+		t.Errorf("expected a total-only log to be priced as prompt tokens, got %v", totalOnly.CostUSD)
+	}
+}
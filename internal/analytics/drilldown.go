@@ -0,0 +1,245 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// Metadata keys the drill-down decomposes spend by
+const (
+	MetadataBeadID   = "bead_id"
+	MetadataAgentID  = "agent_id"
+	MetadataTaskID   = "task_id" // The dispatch session the request belongs to
+	MetadataPersona  = "persona" // The prompt template the agent runs with
+	unattributedCost = "(unattributed)"
+)
+
+// TimeRange is a half-open interval [Start, End)
+type TimeRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// AnomalyWindow returns the ranges the anomaly detector compares: today so
+// far against the seven days before it
+func AnomalyWindow(now time.Time) (window, baseline TimeRange) {
+	startOfToday := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	window = TimeRange{Start: startOfToday, End: now}
+	baseline = TimeRange{Start: startOfToday.Add(-7 * 24 * time.Hour), End: startOfToday}
+	return window, baseline
+}
+
+// DrilldownPath returns the API path of the drill-down comparing window
+// against baseline
+func DrilldownPath(window, baseline TimeRange) string {
+	q := url.Values{}
+	q.Set("start_time", window.Start.Format(time.RFC3339))
+	q.Set("end_time", window.End.Format(time.RFC3339))
+	q.Set("baseline_start", baseline.Start.Format(time.RFC3339))
+	q.Set("baseline_end", baseline.End.Format(time.RFC3339))
+	return "/api/v1/analytics/anomalies/drilldown?" + q.Encode()
+}
+
+// CostContributor is one value of a dimension and how much of the spike it
+// accounts for
+type CostContributor struct {
+	Key             string  `json:"key"`
+	CostUSD         float64 `json:"cost_usd"`
+	BaselineCostUSD float64 `json:"baseline_cost_usd"` // Baseline spend scaled to the window's length
+	IncreaseUSD     float64 `json:"increase_usd"`
+	ShareOfIncrease float64 `json:"share_of_increase"` // Fraction of the total increase, 0 when spend did not rise
+	Requests        int64   `json:"requests"`
+	Tokens          int64   `json:"tokens"`
+}
+
+// DispatchCost is the spend of one dispatch session in the window, with
+// links to its recorded history
+type DispatchCost struct {
+	DispatchID     string            `json:"dispatch_id"`
+	BeadID         string            `json:"bead_id,omitempty"`
+	ProjectID      string            `json:"project_id,omitempty"`
+	AgentID        string            `json:"agent_id,omitempty"`
+	PromptTemplate string            `json:"prompt_template,omitempty"`
+	CostUSD        float64           `json:"cost_usd"`
+	Requests       int64             `json:"requests"`
+	Tokens         int64             `json:"tokens"`
+	Links          map[string]string `json:"links,omitempty"`
+}
+
+// CostDrilldown decomposes the spend of a window against a baseline
+type CostDrilldown struct {
+	Window           TimeRange          `json:"window"`
+	Baseline         TimeRange          `json:"baseline"`
+	CostUSD          float64            `json:"cost_usd"`
+	BaselineCostUSD  float64            `json:"baseline_cost_usd"` // Scaled to the window's length
+	IncreaseUSD      float64            `json:"increase_usd"`
+	Ratio            float64            `json:"ratio"` // Window spend over scaled baseline; 0 without a baseline
+	ByProject        []*CostContributor `json:"by_project"`
+	ByBead           []*CostContributor `json:"by_bead"`
+	ByAgent          []*CostContributor `json:"by_agent"`
+	ByPromptTemplate []*CostContributor `json:"by_prompt_template"`
+	TopDispatches    []*DispatchCost    `json:"top_dispatches"`
+}
+
+// DrilldownCost decomposes the spend of userID (everyone when empty) in
+// window by project, bead, agent and prompt template, ranking each by how
+// much it grew over the baseline, and lists the costliest dispatch
+// sessions. Each list is cut to the top entries.
+func DrilldownCost(ctx context.Context, storage Storage, userID string, window, baseline TimeRange, top int) (*CostDrilldown, error) {
+	if !window.End.After(window.Start) {
+		return nil, fmt.Errorf("window end must be after its start")
+	}
+	if !baseline.End.After(baseline.Start) {
+		return nil, fmt.Errorf("baseline end must be after its start")
+	}
+	if top <= 0 {
+		top = 10
+	}
+
+	current, err := logsIn(ctx, storage, userID, window)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := logsIn(ctx, storage, userID, baseline)
+	if err != nil {
+		return nil, err
+	}
+	scale := float64(window.End.Sub(window.Start)) / float64(baseline.End.Sub(baseline.Start))
+
+	d := &CostDrilldown{Window: window, Baseline: baseline}
+	for _, l := range current {
+		d.CostUSD += l.CostUSD
+	}
+	for _, l := range previous {
+		d.BaselineCostUSD += l.CostUSD * scale
+	}
+	d.IncreaseUSD = d.CostUSD - d.BaselineCostUSD
+	if d.BaselineCostUSD > 0 {
+		d.Ratio = d.CostUSD / d.BaselineCostUSD
+	}
+
+	dimension := func(key string) []*CostContributor {
+		return contributors(current, previous, scale, d.IncreaseUSD, top, func(l *RequestLog) string {
+			return l.Metadata[key]
+		})
+	}
+	d.ByProject = dimension(MetadataProjectID)
+	d.ByBead = dimension(MetadataBeadID)
+	d.ByAgent = dimension(MetadataAgentID)
+	d.ByPromptTemplate = dimension(MetadataPersona)
+	d.TopDispatches = topDispatches(current, top)
+	return d, nil
+}
+
+// logsIn returns the logs in r, excluding its end
+func logsIn(ctx context.Context, storage Storage, userID string, r TimeRange) ([]*RequestLog, error) {
+	logs, err := storage.GetLogs(ctx, &LogFilter{UserID: userID, StartTime: r.Start, EndTime: r.End})
+	if err != nil {
+		return nil, err
+	}
+	out := logs[:0]
+	for _, l := range logs {
+		if l.Timestamp.Before(r.End) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// contributors groups logs by key and ranks the groups by their increase
+// over the scaled baseline
+func contributors(current, previous []*RequestLog, scale, totalIncrease float64, top int, key func(*RequestLog) string) []*CostContributor {
+	groups := make(map[string]*CostContributor)
+	group := func(l *RequestLog) *CostContributor {
+		k := key(l)
+		if k == "" {
+			k = unattributedCost
+		}
+		c, ok := groups[k]
+		if !ok {
+			c = &CostContributor{Key: k}
+			groups[k] = c
+		}
+		return c
+	}
+	for _, l := range current {
+		c := group(l)
+		c.CostUSD += l.CostUSD
+		c.Requests++
+		c.Tokens += l.TotalTokens
+	}
+	for _, l := range previous {
+		group(l).BaselineCostUSD += l.CostUSD * scale
+	}
+
+	list := make([]*CostContributor, 0, len(groups))
+	for _, c := range groups {
+		c.IncreaseUSD = c.CostUSD - c.BaselineCostUSD
+		if totalIncrease > 0 {
+			c.ShareOfIncrease = c.IncreaseUSD / totalIncrease
+		}
+		if c.CostUSD > 0 || c.IncreaseUSD != 0 {
+			list = append(list, c)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].IncreaseUSD != list[j].IncreaseUSD {
+			return list[i].IncreaseUSD > list[j].IncreaseUSD
+		}
+		return list[i].Key < list[j].Key
+	})
+	if len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
+
+// topDispatches returns the costliest dispatch sessions among logs
+func topDispatches(logs []*RequestLog, top int) []*DispatchCost {
+	byID := make(map[string]*DispatchCost)
+	for _, l := range logs {
+		id := l.Metadata[MetadataTaskID]
+		if id == "" {
+			continue
+		}
+		dc, ok := byID[id]
+		if !ok {
+			dc = &DispatchCost{
+				DispatchID:     id,
+				BeadID:         l.Metadata[MetadataBeadID],
+				ProjectID:      l.Metadata[MetadataProjectID],
+				AgentID:        l.Metadata[MetadataAgentID],
+				PromptTemplate: l.Metadata[MetadataPersona],
+			}
+			byID[id] = dc
+		}
+		dc.CostUSD += l.CostUSD
+		dc.Requests++
+		dc.Tokens += l.TotalTokens
+	}
+
+	list := make([]*DispatchCost, 0, len(byID))
+	for _, dc := range byID {
+		if dc.BeadID != "" {
+			bead := url.PathEscape(dc.BeadID)
+			dc.Links = map[string]string{
+				"dispatches": "/api/v1/beads/" + bead + "/dispatches",
+				"artifacts":  "/api/v1/beads/" + bead + "/dispatches/" + url.PathEscape(dc.DispatchID) + "/artifacts",
+			}
+		}
+		list = append(list, dc)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CostUSD != list[j].CostUSD {
+			return list[i].CostUSD > list[j].CostUSD
+		}
+		return list[i].DispatchID < list[j].DispatchID
+	})
+	if len(list) > top {
+		list = list[:top]
+	}
+	return list
+}
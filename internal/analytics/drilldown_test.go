@@ -0,0 +1,89 @@
+package analytics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrilldownCost(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryStorage()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	window, baseline := AnomalyWindow(now)
+
+	save := func(at time.Time, cost float64, project, bead, agent, persona, task string) {
+		_ = storage.SaveLog(ctx, &RequestLog{
+			Timestamp:   at,
+			CostUSD:     cost,
+			TotalTokens: 1000,
+			Metadata: map[string]string{
+				MetadataProjectID: project,
+				MetadataBeadID:    bead,
+				MetadataAgentID:   agent,
+				MetadataPersona:   persona,
+				MetadataTaskID:    task,
+			},
+		})
+	}
+	// Steady baseline: $1.40 a day on proj-a, nothing on proj-b
+	for day := 1; day <= 7; day++ {
+		save(window.Start.Add(-time.Duration(day)*24*time.Hour+time.Hour), 1.40, "proj-a", "bead-1", "agent-1", "coder", "task-old")
+	}
+	// Today proj-b spikes
+	save(window.Start.Add(time.Hour), 0.50, "proj-a", "bead-1", "agent-1", "coder", "task-1")
+	save(window.Start.Add(2*time.Hour), 3.00, "proj-b", "bead-2", "agent-2", "reviewer", "task-2")
+	save(window.Start.Add(3*time.Hour), 2.00, "proj-b", "bead-2", "agent-2", "reviewer", "task-2")
+	save(window.Start.Add(4*time.Hour), 0.25, "", "", "", "", "")
+
+	d, err := DrilldownCost(ctx, storage, "", window, baseline, 5)
+	if err != nil {
+		t.Fatalf("DrilldownCost failed: %v", err)
+	}
+	if d.CostUSD != 5.75 {
+		t.Errorf("expected window cost 5.75, got %.2f", d.CostUSD)
+	}
+	// The baseline is scaled to the half day of the window
+	if diff := d.BaselineCostUSD - 0.70; diff > 1e-9 || diff < -1e-9 {
+		t.Errorf("expected scaled baseline 0.70, got %.4f", d.BaselineCostUSD)
+	}
+
+	if len(d.ByProject) != 3 || d.ByProject[0].Key != "proj-b" || d.ByProject[0].IncreaseUSD != 5.00 {
+		t.Fatalf("expected proj-b to lead with a $5 increase, got %+v", d.ByProject)
+	}
+	if d.ByProject[0].ShareOfIncrease < 0.9 {
+		t.Errorf("expected proj-b to account for most of the increase, got %.2f", d.ByProject[0].ShareOfIncrease)
+	}
+	if d.ByPromptTemplate[0].Key != "reviewer" || d.ByAgent[0].Key != "agent-2" || d.ByBead[0].Key != "bead-2" {
+		t.Errorf("unexpected leaders: template %s, agent %s, bead %s",
+			d.ByPromptTemplate[0].Key, d.ByAgent[0].Key, d.ByBead[0].Key)
+	}
+
+	if len(d.TopDispatches) != 2 {
+		t.Fatalf("expected two dispatches in the window, got %+v", d.TopDispatches)
+	}
+	top := d.TopDispatches[0]
+	if top.DispatchID != "task-2" || top.Requests != 2 || top.CostUSD != 5.00 {
+		t.Errorf("expected task-2 to be the costliest dispatch, got %+v", top)
+	}
+	if top.Links["artifacts"] != "/api/v1/beads/bead-2/dispatches/task-2/artifacts" {
+		t.Errorf("unexpected artifacts link %q", top.Links["artifacts"])
+	}
+}
+
+func TestDrilldownCostRejectsEmptyWindow(t *testing.T) {
+	now := time.Now()
+	r := TimeRange{Start: now, End: now}
+	if _, err := DrilldownCost(context.Background(), NewInMemoryStorage(), "", r, TimeRange{Start: now.Add(-time.Hour), End: now}, 0); err == nil {
+		t.Error("expected an empty window to be rejected")
+	}
+}
+
+func TestDrilldownPath(t *testing.T) {
+	window, baseline := AnomalyWindow(time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC))
+	p := DrilldownPath(window, baseline)
+	if !strings.HasPrefix(p, "/api/v1/analytics/anomalies/drilldown?") || !strings.Contains(p, "baseline_start=2026-03-03T00%3A00%3A00Z") {
+		t.Errorf("unexpected drill-down path %q", p)
+	}
+}
@@ -0,0 +1,206 @@
+package analytics
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Export formats
+const (
+	ExportCSV     = "csv"
+	ExportParquet = "parquet"
+)
+
+// exportBatchSize is how many logs an export reads, writes and flushes at
+// a time
+const exportBatchSize = 1000
+
+// ExportFilter selects the logs an export streams. Logs are exported
+// oldest first, so an interrupted export resumes with AfterID set to the
+// ID of the last log received.
+type ExportFilter struct {
+	UserID     string
+	ProviderID string
+	ProjectID  string
+	StartTime  time.Time
+	EndTime    time.Time
+	AfterID    string
+	Limit      int // Rows to export at most; 0 exports all
+}
+
+// ExportWriter writes batches of logs in an export format. Close finishes
+// the output without closing the underlying writer.
+type ExportWriter interface {
+	Write(logs []*RequestLog) error
+	Close() error
+}
+
+// NewExportWriter returns a writer of format to w
+func NewExportWriter(format string, w io.Writer) (ExportWriter, error) {
+	switch format {
+	case ExportCSV:
+		return newCSVExport(w), nil
+	case ExportParquet:
+		return newParquetExport(w), nil
+	default:
+		return nil, fmt.Errorf("invalid format %q (expected csv or parquet)", format)
+	}
+}
+
+// Export streams the logs matching f to out in batches, calling flush
+// after each so the output reaches the client as it is produced. Request
+// and response bodies are never exported. It returns how many logs were
+// written.
+func Export(ctx context.Context, storage Storage, f ExportFilter, out ExportWriter, flush func()) (int, error) {
+	filter := &LogFilter{
+		UserID:     f.UserID,
+		ProviderID: f.ProviderID,
+		StartTime:  f.StartTime,
+		EndTime:    f.EndTime,
+		Ascending:  true,
+		Limit:      exportBatchSize,
+	}
+	if f.AfterID != "" {
+		// Resume from the log as stored, whatever precision and zone the
+		// client received its timestamp in
+		after, err := storage.GetLogs(ctx, &LogFilter{ID: f.AfterID})
+		if err != nil {
+			return 0, err
+		}
+		if len(after) == 0 {
+			return 0, fmt.Errorf("log %s not found", f.AfterID)
+		}
+		filter.After = &LogKey{Timestamp: after[0].Timestamp, ID: after[0].ID}
+	}
+	written := 0
+	for {
+		logs, err := storage.GetLogs(ctx, filter)
+		if err != nil {
+			return written, err
+		}
+		batch := logs[:0:0]
+		for _, l := range logs {
+			if f.ProjectID != "" && l.Metadata[MetadataProjectID] != f.ProjectID {
+				continue
+			}
+			if f.Limit > 0 && written+len(batch) == f.Limit {
+				break
+			}
+			batch = append(batch, l)
+		}
+		if len(batch) > 0 {
+			if err := out.Write(batch); err != nil {
+				return written, err
+			}
+			written += len(batch)
+			if flush != nil {
+				flush()
+			}
+		}
+		if len(logs) < exportBatchSize || (f.Limit > 0 && written == f.Limit) {
+			return written, nil
+		}
+		last := logs[len(logs)-1]
+		filter.After = &LogKey{Timestamp: last.Timestamp, ID: last.ID}
+	}
+}
+
+// exportColumn is a column of an export and how to read it from a log
+type exportColumn struct {
+	name  string
+	kind  int // exportString, exportInt, exportFloat or exportTime
+	value func(l *RequestLog) interface{}
+}
+
+// Column kinds: string, int64, float64 and time.Time values
+const (
+	exportString = iota
+	exportInt
+	exportFloat
+	exportTime
+)
+
+// exportColumns are the columns of every export format. id is the key to
+// resume an export after.
+var exportColumns = []exportColumn{
+	{"id", exportString, func(l *RequestLog) interface{} { return l.ID }},
+	{"timestamp", exportTime, func(l *RequestLog) interface{} { return l.Timestamp }},
+	{"user_id", exportString, func(l *RequestLog) interface{} { return l.UserID }},
+	{"project_id", exportString, func(l *RequestLog) interface{} { return l.Metadata[MetadataProjectID] }},
+	{"bead_id", exportString, func(l *RequestLog) interface{} { return l.Metadata[MetadataBeadID] }},
+	{"agent_id", exportString, func(l *RequestLog) interface{} { return l.Metadata[MetadataAgentID] }},
+	{"provider_id", exportString, func(l *RequestLog) interface{} { return l.ProviderID }},
+	{"model", exportString, func(l *RequestLog) interface{} { return l.ModelName }},
+	{"method", exportString, func(l *RequestLog) interface{} { return l.Method }},
+	{"path", exportString, func(l *RequestLog) interface{} { return l.Path }},
+	{"prompt_tokens", exportInt, func(l *RequestLog) interface{} { return l.PromptTokens }},
+	{"completion_tokens", exportInt, func(l *RequestLog) interface{} { return l.CompletionTokens }},
+	{"total_tokens", exportInt, func(l *RequestLog) interface{} { return l.TotalTokens }},
+	{"latency_ms", exportInt, func(l *RequestLog) interface{} { return l.LatencyMs }},
+	{"status_code", exportInt, func(l *RequestLog) interface{} { return int64(l.StatusCode) }},
+	{"cost_usd", exportFloat, func(l *RequestLog) interface{} { return l.CostUSD }},
+	{"error_message", exportString, func(l *RequestLog) interface{} { return l.ErrorMessage }},
+}
+
+// csvExport writes a header row, then one row per log, with timestamps in
+// UTC
+type csvExport struct {
+	w      *csv.Writer
+	header bool
+}
+
+func newCSVExport(w io.Writer) *csvExport {
+	return &csvExport{w: csv.NewWriter(w)}
+}
+
+func (e *csvExport) Write(logs []*RequestLog) error {
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(exportColumns))
+	for _, l := range logs {
+		for i, c := range exportColumns {
+			switch v := c.value(l).(type) {
+			case string:
+				row[i] = v
+			case int64:
+				row[i] = strconv.FormatInt(v, 10)
+			case float64:
+				row[i] = strconv.FormatFloat(v, 'f', -1, 64)
+			case time.Time:
+				row[i] = v.UTC().Format(time.RFC3339Nano)
+			}
+		}
+		if err := e.w.Write(row); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExport) writeHeader() error {
+	e.header = true
+	names := make([]string, len(exportColumns))
+	for i, c := range exportColumns {
+		names[i] = c.name
+	}
+	return e.w.Write(names)
+}
+
+// Close writes the header of an empty export
+func (e *csvExport) Close() error {
+	if !e.header {
+		if err := e.writeHeader(); err != nil {
+			return err
+		}
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,221 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/csv"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// exportTestStorage holds 2500 logs, three to a timestamp so batches end
+// between logs of the same instant; every fifth is another project's
+func exportTestStorage(t *testing.T) *DatabaseStorage {
+	t.Helper()
+	storage, err := NewDatabaseStorage(newTestDB(t))
+	if err != nil {
+		t.Fatalf("NewDatabaseStorage failed: %v", err)
+	}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2500; i++ {
+		project := "proj-a"
+		if i%5 == 0 {
+			project = "proj-b"
+		}
+		err := storage.SaveLog(context.Background(), &RequestLog{
+			ID:          fmt.Sprintf("log-%05d", i),
+			Timestamp:   start.Add(time.Duration(i/3) * time.Second),
+			UserID:      "user-1",
+			Method:      "POST",
+			Path:        "/v1/chat/completions",
+			ProviderID:  "openai",
+			TotalTokens: int64(i),
+			CostUSD:     0.25,
+			RequestBody: "secret prompt",
+			Metadata:    map[string]string{MetadataProjectID: project},
+		})
+		if err != nil {
+			t.Fatalf("SaveLog failed: %v", err)
+		}
+	}
+	return storage
+}
+
+func exportCSV(t *testing.T, storage Storage, f ExportFilter) ([][]string, int) {
+	t.Helper()
+	var buf bytes.Buffer
+	out, err := NewExportWriter(ExportCSV, &buf)
+	if err != nil {
+		t.Fatalf("NewExportWriter failed: %v", err)
+	}
+	flushes := 0
+	n, err := Export(context.Background(), storage, f, out, func() { flushes++ })
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(records)-1 != n {
+		t.Errorf("Export reported %d rows, wrote %d", n, len(records)-1)
+	}
+	return records, flushes
+}
+
+func TestExport_CSVStreamsAllInOrder(t *testing.T) {
+	storage := exportTestStorage(t)
+	records, flushes := exportCSV(t, storage, ExportFilter{ProjectID: "proj-a"})
+
+	if len(records) != 2001 {
+		t.Fatalf("expected a header and 2000 rows, got %d records", len(records))
+	}
+	if flushes != 3 {
+		t.Errorf("expected a flush per batch, got %d", flushes)
+	}
+	for i, rec := range records[1:] {
+		if rec[3] != "proj-a" {
+			t.Fatalf("row %d is from project %s", i, rec[3])
+		}
+		if i > 0 && rec[0] <= records[i][0] {
+			t.Fatalf("rows out of order or repeated at %d: %s after %s", i, rec[0], records[i][0])
+		}
+	}
+	for _, rec := range records {
+		for _, v := range rec {
+			if v == "secret prompt" {
+				t.Fatal("request bodies must not be exported")
+			}
+		}
+	}
+}
+
+func TestExport_ResumesAfterLastRow(t *testing.T) {
+	storage := exportTestStorage(t)
+	first, _ := exportCSV(t, storage, ExportFilter{Limit: 1001})
+	if len(first) != 1002 {
+		t.Fatalf("expected the limit to cap the export, got %d records", len(first))
+	}
+	last := first[len(first)-1][0]
+	rest, _ := exportCSV(t, storage, ExportFilter{AfterID: last})
+	if len(rest) != 1500 {
+		t.Fatalf("expected the remaining 1499 rows, got %d records", len(rest))
+	}
+	if rest[1][0] != "log-01001" {
+		t.Errorf("expected the export to resume right after %s, got %s", last, rest[1][0])
+	}
+
+	out, _ := NewExportWriter(ExportCSV, &bytes.Buffer{})
+	if _, err := Export(context.Background(), storage, ExportFilter{AfterID: "missing"}, out, nil); err == nil {
+		t.Error("expected an unknown resume ID to fail")
+	}
+}
+
+func TestExport_Parquet(t *testing.T) {
+	storage := exportTestStorage(t)
+	var buf bytes.Buffer
+	out, _ := NewExportWriter(ExportParquet, &buf)
+	n, err := Export(context.Background(), storage, ExportFilter{}, out, nil)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data := buf.Bytes()
+	if string(data[:4]) != "PAR1" || string(data[len(data)-4:]) != "PAR1" {
+		t.Fatal("expected the Parquet magic at both ends")
+	}
+	footerLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footer := data[len(data)-8-footerLen : len(data)-8]
+	meta := readThriftStruct(t, bytes.NewReader(footer))
+
+	if meta[3] != int64(n) || n != 2500 {
+		t.Errorf("expected num_rows %d, got %v", n, meta[3])
+	}
+	schema := meta[2].([]interface{})
+	if len(schema) != len(exportColumns)+1 {
+		t.Fatalf("expected a root and %d columns, got %d schema elements", len(exportColumns), len(schema))
+	}
+	if name := schema[1].(map[int16]interface{})[4]; name != "id" {
+		t.Errorf("expected the first column to be id, got %v", name)
+	}
+	rowGroups := meta[4].([]interface{})
+	if len(rowGroups) != 3 {
+		t.Fatalf("expected a row group per batch, got %d", len(rowGroups))
+	}
+
+	// The first value of the id column chunk follows its page header
+	chunk := rowGroups[0].(map[int16]interface{})[1].([]interface{})[0].(map[int16]interface{})
+	r := bytes.NewReader(data[chunk[2].(int64):])
+	header := readThriftStruct(t, r)
+	if header[5].(map[int16]interface{})[1] != int64(1000) {
+		t.Errorf("expected 1000 values in the first page, got %v", header[5])
+	}
+	var size uint32
+	_ = binary.Read(r, binary.LittleEndian, &size)
+	first := make([]byte, size)
+	_, _ = r.Read(first)
+	if string(first) != "log-00000" {
+		t.Errorf("expected the first id to be log-00000, got %q", first)
+	}
+}
+
+// readThriftStruct decodes a compact-protocol struct into its fields by ID.
+// Integers decode as int64, binaries as strings, lists as slices.
+func readThriftStruct(t *testing.T, r *bytes.Reader) map[int16]interface{} {
+	t.Helper()
+	fields := make(map[int16]interface{})
+	var id int16
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			t.Fatalf("truncated struct: %v", err)
+		}
+		if b == 0 {
+			return fields
+		}
+		if delta := int16(b >> 4); delta != 0 {
+			id += delta
+		} else {
+			v, _ := binary.ReadVarint(r)
+			id = int16(v)
+		}
+		fields[id] = readThriftValue(t, r, b&0x0f)
+	}
+}
+
+func readThriftValue(t *testing.T, r *bytes.Reader, typ byte) interface{} {
+	switch typ {
+	case 5, 6:
+		v, _ := binary.ReadVarint(r)
+		return v
+	case 8:
+		n, _ := binary.ReadUvarint(r)
+		b := make([]byte, n)
+		_, _ = r.Read(b)
+		return string(b)
+	case 9:
+		h, _ := r.ReadByte()
+		n := uint64(h >> 4)
+		if n == 15 {
+			n, _ = binary.ReadUvarint(r)
+		}
+		list := make([]interface{}, n)
+		for i := range list {
+			list[i] = readThriftValue(t, r, h&0x0f)
+		}
+		return list
+	case 12:
+		return readThriftStruct(t, r)
+	default:
+		t.Fatalf("unexpected thrift type %d", typ)
+		return nil
+	}
+}
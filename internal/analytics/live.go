@@ -0,0 +1,169 @@
+package analytics
+
+import (
+	"sync"
+	"time"
+)
+
+// liveBuckets is how many one-second buckets LiveMetrics keeps: an hour
+const liveBuckets = 3600
+
+// liveMinute is the number of buckets in the short window rates are
+// computed over
+const liveMinute = 60
+
+// liveCounts are the totals of one bucket or window
+type liveCounts struct {
+	requests    int64
+	errors      int64
+	tokens      int64
+	costUSD     float64
+	cacheHits   int64
+	cacheMisses int64
+}
+
+func (c *liveCounts) add(o liveCounts) {
+	c.requests += o.requests
+	c.errors += o.errors
+	c.tokens += o.tokens
+	c.costUSD += o.costUSD
+	c.cacheHits += o.cacheHits
+	c.cacheMisses += o.cacheMisses
+}
+
+func (c *liveCounts) sub(o liveCounts) {
+	c.requests -= o.requests
+	c.errors -= o.errors
+	c.tokens -= o.tokens
+	c.costUSD -= o.costUSD
+	c.cacheHits -= o.cacheHits
+	c.cacheMisses -= o.cacheMisses
+	if c.requests == 0 {
+		c.costUSD = 0 // Don't let rounding leave spend behind
+	}
+}
+
+// LiveMetrics keeps rolling totals of the last minute and hour of requests
+// and cache lookups. Each is counted into a one-second bucket and into the
+// window totals as it is recorded; buckets are subtracted again as they
+// leave a window, so reading the totals never scans logs.
+type LiveMetrics struct {
+	mu      sync.Mutex
+	buckets [liveBuckets]liveCounts
+	newest  int64 // Unix second of the newest bucket
+	minute  liveCounts
+	hour    liveCounts
+}
+
+// LiveSnapshot is the state of LiveMetrics at one instant. The agent counts
+// are not tracked by LiveMetrics and are filled in by the caller.
+type LiveSnapshot struct {
+	Timestamp          time.Time      `json:"timestamp"`
+	SpendLastMinuteUSD float64        `json:"spend_last_minute_usd"`
+	SpendLastHourUSD   float64        `json:"spend_last_hour_usd"`
+	SpendPerHourUSD    float64        `json:"spend_per_hour_usd"` // The last minute's spend rate
+	TokensPerSecond    float64        `json:"tokens_per_second"`  // Over the last minute
+	RequestsLastMinute int64          `json:"requests_last_minute"`
+	RequestsLastHour   int64          `json:"requests_last_hour"`
+	ErrorRate          float64        `json:"error_rate"`     // Over the last minute
+	CacheHitRate       float64        `json:"cache_hit_rate"` // Over the last hour
+	CacheLookups       int64          `json:"cache_lookups"`  // Over the last hour
+	ActiveAgents       int            `json:"active_agents"`
+	AgentsByStatus     map[string]int `json:"agents_by_status,omitempty"`
+}
+
+// NewLiveMetrics creates empty live metrics
+func NewLiveMetrics() *LiveMetrics {
+	return &LiveMetrics{}
+}
+
+// Record counts a logged request at its timestamp
+func (m *LiveMetrics) Record(log *RequestLog) {
+	c := liveCounts{
+		requests: 1,
+		tokens:   log.TotalTokens,
+		costUSD:  log.CostUSD,
+	}
+	if log.StatusCode >= 400 {
+		c.errors = 1
+	}
+	at := log.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	m.record(at, c)
+}
+
+// RecordCacheLookup counts a response cache hit or miss
+func (m *LiveMetrics) RecordCacheLookup(hit bool) {
+	c := liveCounts{cacheMisses: 1}
+	if hit {
+		c = liveCounts{cacheHits: 1}
+	}
+	m.record(time.Now(), c)
+}
+
+func (m *LiveMetrics) record(at time.Time, c liveCounts) {
+	sec := at.Unix()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance(sec)
+	// Requests logged late still count, if their second is in the window
+	if sec <= m.newest-liveBuckets {
+		return
+	}
+	m.buckets[sec%liveBuckets].add(c)
+	m.hour.add(c)
+	if sec > m.newest-liveMinute {
+		m.minute.add(c)
+	}
+}
+
+// advance moves the newest bucket forward to sec, dropping the buckets
+// that leave each window from its totals
+func (m *LiveMetrics) advance(sec int64) {
+	if sec <= m.newest {
+		return
+	}
+	if sec-m.newest >= liveBuckets {
+		m.buckets = [liveBuckets]liveCounts{}
+		m.minute = liveCounts{}
+		m.hour = liveCounts{}
+		m.newest = sec
+		return
+	}
+	for s := m.newest + 1; s <= sec; s++ {
+		m.minute.sub(m.buckets[(s-liveMinute)%liveBuckets])
+		// The bucket for s last held the second an hour before it
+		old := &m.buckets[s%liveBuckets]
+		m.hour.sub(*old)
+		*old = liveCounts{}
+	}
+	m.newest = sec
+}
+
+// Snapshot returns the rolling totals as of now
+func (m *LiveMetrics) Snapshot(now time.Time) *LiveSnapshot {
+	m.mu.Lock()
+	m.advance(now.Unix())
+	minute, hour := m.minute, m.hour
+	m.mu.Unlock()
+
+	s := &LiveSnapshot{
+		Timestamp:          now,
+		SpendLastMinuteUSD: minute.costUSD,
+		SpendLastHourUSD:   hour.costUSD,
+		SpendPerHourUSD:    minute.costUSD * 60,
+		TokensPerSecond:    float64(minute.tokens) / liveMinute,
+		RequestsLastMinute: minute.requests,
+		RequestsLastHour:   hour.requests,
+		CacheLookups:       hour.cacheHits + hour.cacheMisses,
+	}
+	if minute.requests > 0 {
+		s.ErrorRate = float64(minute.errors) / float64(minute.requests)
+	}
+	if s.CacheLookups > 0 {
+		s.CacheHitRate = float64(hour.cacheHits) / float64(s.CacheLookups)
+	}
+	return s
+}
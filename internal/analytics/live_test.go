@@ -0,0 +1,82 @@
+package analytics
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLiveMetrics_RollingWindows(t *testing.T) {
+	m := NewLiveMetrics()
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	m.Record(&RequestLog{Timestamp: start, TotalTokens: 600, CostUSD: 1.5, StatusCode: 200})
+	m.Record(&RequestLog{Timestamp: start.Add(30 * time.Second), TotalTokens: 1200, CostUSD: 0.5, StatusCode: 500})
+
+	s := m.Snapshot(start.Add(30 * time.Second))
+	if s.RequestsLastMinute != 2 || s.SpendLastMinuteUSD != 2 {
+		t.Fatalf("expected both requests in the last minute, got %d costing %v", s.RequestsLastMinute, s.SpendLastMinuteUSD)
+	}
+	if s.TokensPerSecond != 30 {
+		t.Errorf("expected 1800 tokens over a minute to be 30/s, got %v", s.TokensPerSecond)
+	}
+	if s.ErrorRate != 0.5 {
+		t.Errorf("expected an error rate of 0.5, got %v", s.ErrorRate)
+	}
+	if s.SpendPerHourUSD != 120 {
+		t.Errorf("expected $2 a minute to be $120 an hour, got %v", s.SpendPerHourUSD)
+	}
+
+	// The first request leaves the minute but not the hour
+	s = m.Snapshot(start.Add(time.Minute))
+	if s.RequestsLastMinute != 1 || s.SpendLastMinuteUSD != 0.5 {
+		t.Errorf("expected one request left in the minute, got %d costing %v", s.RequestsLastMinute, s.SpendLastMinuteUSD)
+	}
+	if s.RequestsLastHour != 2 || s.SpendLastHourUSD != 2 {
+		t.Errorf("expected both requests in the hour, got %d costing %v", s.RequestsLastHour, s.SpendLastHourUSD)
+	}
+
+	// A request logged late lands in its own second
+	m.Record(&RequestLog{Timestamp: start.Add(40 * time.Second), CostUSD: 0.25})
+	s = m.Snapshot(start.Add(time.Minute + 30*time.Second))
+	if s.RequestsLastMinute != 1 || s.SpendLastMinuteUSD != 0.25 {
+		t.Errorf("expected only the late request in the minute, got %d costing %v", s.RequestsLastMinute, s.SpendLastMinuteUSD)
+	}
+
+	s = m.Snapshot(start.Add(time.Hour + 30*time.Second))
+	if s.RequestsLastHour != 1 || math.Abs(s.SpendLastHourUSD-0.25) > 1e-9 {
+		t.Errorf("expected only the late request in the hour, got %d costing %v", s.RequestsLastHour, s.SpendLastHourUSD)
+	}
+
+	s = m.Snapshot(start.Add(3 * time.Hour))
+	if s.RequestsLastHour != 0 || s.SpendLastHourUSD != 0 {
+		t.Errorf("expected an idle hour to be empty, got %d costing %v", s.RequestsLastHour, s.SpendLastHourUSD)
+	}
+}
+
+func TestLiveMetrics_CacheHitRate(t *testing.T) {
+	m := NewLiveMetrics()
+	m.RecordCacheLookup(true)
+	m.RecordCacheLookup(true)
+	m.RecordCacheLookup(true)
+	m.RecordCacheLookup(false)
+
+	s := m.Snapshot(time.Now())
+	if s.CacheLookups != 4 || s.CacheHitRate != 0.75 {
+		t.Errorf("expected 4 lookups at 0.75, got %d at %v", s.CacheLookups, s.CacheHitRate)
+	}
+}
+
+func TestLogger_RecordsLiveMetrics(t *testing.T) {
+	m := NewLiveMetrics()
+	logger := NewLogger(NewInMemoryStorage(), nil)
+	logger.SetLiveMetrics(m)
+
+	if err := logger.LogRequest(context.Background(), &RequestLog{TotalTokens: 60, CostUSD: 0.1}); err != nil {
+		t.Fatalf("LogRequest failed: %v", err)
+	}
+	if s := m.Snapshot(time.Now()); s.RequestsLastMinute != 1 || s.SpendLastHourUSD != 0.1 {
+		t.Errorf("expected the logged request to be counted, got %d costing %v", s.RequestsLastMinute, s.SpendLastHourUSD)
+	}
+}
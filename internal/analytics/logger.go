@@ -58,8 +58,17 @@ func DefaultPrivacyConfig() *PrivacyConfig {
 
 // Logger handles request/response logging with privacy controls
 type Logger struct {
-	storage Storage
-	privacy *PrivacyConfig
+	storage    Storage
+	privacy    *PrivacyConfig
+	compliance ComplianceLookup
+	live       *LiveMetrics
+	pricer     Pricer
+}
+
+// Pricer prices requests from per-model token rates, reporting false for
+// models it has no price for
+type Pricer interface {
+	Cost(providerID, model string, promptTokens, completionTokens, cachedTokens int64) (float64, bool)
 }
 
 // Storage interface for persisting logs
@@ -72,12 +81,23 @@ type Storage interface {
 
 // LogFilter for querying logs
 type LogFilter struct {
+	ID         string // Only the log with this ID
 	UserID     string
 	ProviderID string
 	StartTime  time.Time
 	EndTime    time.Time
 	Limit      int
 	Offset     int
+	// Ascending lists logs oldest first, by timestamp then ID, instead of
+	// newest first. After continues such a listing past the given log.
+	Ascending bool
+	After     *LogKey
+}
+
+// LogKey is the position of a log in an ascending listing
+type LogKey struct {
+	Timestamp time.Time
+	ID        string
 }
 
 // LogStats provides aggregate statistics
@@ -109,25 +129,30 @@ func NewLogger(storage Storage, privacy *PrivacyConfig) *Logger {
 
 // LogRequest logs an API request with privacy controls
 func (l *Logger) LogRequest(ctx context.Context, log *RequestLog) error {
-	// Apply privacy filters
-	if !l.privacy.LogRequestBodies {
+	// Apply privacy filters, tightened by the project's compliance constraints
+	privacy, compliance := l.privacyFor(log)
+	if !privacy.LogRequestBodies {
 		log.RequestBody = "" // Don't log request bodies
-	} else if l.privacy.MaxBodyLength > 0 && len(log.RequestBody) > l.privacy.MaxBodyLength {
-		log.RequestBody = log.RequestBody[:l.privacy.MaxBodyLength] + "... [truncated]"
+	} else if privacy.MaxBodyLength > 0 && len(log.RequestBody) > privacy.MaxBodyLength {
+		log.RequestBody = log.RequestBody[:privacy.MaxBodyLength] + "... [truncated]"
 	}
 
-	if !l.privacy.LogResponseBodies {
+	if !privacy.LogResponseBodies {
 		log.ResponseBody = "" // Don't log response bodies
-	} else if l.privacy.MaxBodyLength > 0 && len(log.ResponseBody) > l.privacy.MaxBodyLength {
-		log.ResponseBody = log.ResponseBody[:l.privacy.MaxBodyLength] + "... [truncated]"
+	} else if privacy.MaxBodyLength > 0 && len(log.ResponseBody) > privacy.MaxBodyLength {
+		log.ResponseBody = log.ResponseBody[:privacy.MaxBodyLength] + "... [truncated]"
 	}
 
 	// Redact sensitive patterns
 	if log.RequestBody != "" {
-		log.RequestBody = l.redactSensitiveData(log.RequestBody)
+		log.RequestBody = redactSensitiveData(privacy, log.RequestBody)
 	}
 	if log.ResponseBody != "" {
-		log.ResponseBody = l.redactSensitiveData(log.ResponseBody)
+		log.ResponseBody = redactSensitiveData(privacy, log.ResponseBody)
+	}
+	// Mandatory redaction also covers error text, which often echoes input
+	if compliance != nil && compliance.RequireRedaction && log.ErrorMessage != "" {
+		log.ErrorMessage = redactSensitiveData(privacy, log.ErrorMessage)
 	}
 
 	// Generate ID if not provided
@@ -140,7 +165,36 @@ func (l *Logger) LogRequest(ctx context.Context, log *RequestLog) error {
 		log.Timestamp = time.Now()
 	}
 
-	return l.storage.SaveLog(ctx, log)
+	// Price the request from the catalog when it knows the model, so every
+	// report and budget agrees on what a request cost
+	if l.pricer != nil {
+		prompt, completion := log.PromptTokens, log.CompletionTokens
+		if prompt+completion == 0 {
+			prompt = log.TotalTokens // Only the total is known
+		}
+		if cost, ok := l.pricer.Cost(log.ProviderID, log.ModelName, prompt, completion, 0); ok {
+			log.CostUSD = cost
+		}
+	}
+
+	if err := l.storage.SaveLog(ctx, log); err != nil {
+		return err
+	}
+	if l.live != nil {
+		l.live.Record(log)
+	}
+	return nil
+}
+
+// SetPricer makes the logger cost requests with p instead of trusting the
+// cost it is given
+func (l *Logger) SetPricer(p Pricer) {
+	l.pricer = p
+}
+
+// SetLiveMetrics makes the logger count every saved request into m
+func (l *Logger) SetLiveMetrics(m *LiveMetrics) {
+	l.live = m
 }
 
 // GetLogs retrieves logs with filtering
@@ -153,14 +207,24 @@ func (l *Logger) GetStats(ctx context.Context, filter *LogFilter) (*LogStats, er
 	return l.storage.GetLogStats(ctx, filter)
 }
 
+// Export streams the logs matching f to out, see Export
+func (l *Logger) Export(ctx context.Context, f ExportFilter, out ExportWriter, flush func()) (int, error) {
+	return Export(ctx, l.storage, f, out, flush)
+}
+
+// Drilldown decomposes spend in window against baseline, see DrilldownCost
+func (l *Logger) Drilldown(ctx context.Context, userID string, window, baseline TimeRange, top int) (*CostDrilldown, error) {
+	return DrilldownCost(ctx, l.storage, userID, window, baseline, top)
+}
+
 // PurgeLogs deletes logs older than the specified time
 func (l *Logger) PurgeLogs(ctx context.Context, before time.Time) (int64, error) {
 	return l.storage.DeleteOldLogs(ctx, before)
 }
 
 // redactSensitiveData applies privacy redaction patterns
-func (l *Logger) redactSensitiveData(data string) string {
-	for _, pattern := range l.privacy.RedactPatterns {
+func redactSensitiveData(privacy *PrivacyConfig, data string) string {
+	for _, pattern := range privacy.RedactPatterns {
 		re, err := regexp.Compile(pattern)
 		if err != nil {
 			continue // Skip invalid patterns
@@ -4,6 +4,8 @@ import (
 	"context"
 	"testing"
 	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
 )
 
 // MockStorage for testing
@@ -163,6 +165,47 @@ func TestLogRequest_BodyTruncation(t *testing.T) {
 	}
 }
 
+func TestLogRequest_ComplianceConstraints(t *testing.T) {
+	storage := &MockStorage{}
+	privacy := &PrivacyConfig{
+		LogRequestBodies:  true,
+		LogResponseBodies: true,
+	}
+	logger := NewLogger(storage, privacy)
+	logger.SetComplianceLookup(func(projectID string) *models.ComplianceConstraints {
+		if projectID == "regulated" {
+			return &models.ComplianceConstraints{NoBodyLogging: true, RequireRedaction: true}
+		}
+		return nil
+	})
+
+	regulated := &RequestLog{
+		RequestBody:  `{"prompt":"hi"}`,
+		ResponseBody: `{"response":"hello"}`,
+		ErrorMessage: "rejected input from user@example.com",
+		Metadata:     map[string]string{MetadataProjectID: "regulated"},
+	}
+	other := &RequestLog{
+		RequestBody: `{"prompt":"hi"}`,
+		Metadata:    map[string]string{MetadataProjectID: "open"},
+	}
+	for _, l := range []*RequestLog{regulated, other} {
+		if err := logger.LogRequest(context.Background(), l); err != nil {
+			t.Fatalf("LogRequest failed: %v", err)
+		}
+	}
+
+	if storage.logs[0].RequestBody != "" || storage.logs[0].ResponseBody != "" {
+		t.Error("bodies must not be logged for a project with no_body_logging")
+	}
+	if storage.logs[0].ErrorMessage != "rejected input from [REDACTED]" {
+		t.Errorf("expected mandatory redaction of the error, got %q", storage.logs[0].ErrorMessage)
+	}
+	if storage.logs[1].RequestBody == "" {
+		t.Error("unconstrained projects keep the logger's own privacy config")
+	}
+}
+
 func TestCalculateCost(t *testing.T) {
 	tests := []struct {
 		name          string
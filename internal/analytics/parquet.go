@@ -0,0 +1,274 @@
+package analytics
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"time"
+)
+
+// A minimal Parquet writer: flat schemas of required columns, one row group
+// per batch, each column chunk a single uncompressed data page in PLAIN
+// encoding. Metadata is serialized with Thrift's compact protocol, as the
+// format requires.
+const parquetMagic = "PAR1"
+
+// Parquet physical types, converted types, encodings and page types
+const (
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetUTF8            = 0
+	parquetTimestampMicros = 10
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetRequired = 0
+	parquetDataPage = 0
+)
+
+// Thrift compact protocol field types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// parquetColumnChunk is where a written column chunk is and how large
+type parquetColumnChunk struct {
+	offset int64
+	size   int64
+}
+
+// parquetRowGroup records a written row group for the footer
+type parquetRowGroup struct {
+	rows    int64
+	columns []parquetColumnChunk
+}
+
+// parquetExport streams logs as a Parquet file. Each batch becomes a row
+// group as soon as it is written, so memory stays bounded by the batch
+// size; the footer describing them all is written by Close.
+type parquetExport struct {
+	w         io.Writer
+	offset    int64
+	rowGroups []parquetRowGroup
+	err       error
+}
+
+func newParquetExport(w io.Writer) *parquetExport {
+	return &parquetExport{w: w}
+}
+
+func (e *parquetExport) write(b []byte) {
+	if e.err != nil {
+		return
+	}
+	n, err := e.w.Write(b)
+	e.offset += int64(n)
+	e.err = err
+}
+
+func (e *parquetExport) Write(logs []*RequestLog) error {
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+	if len(logs) == 0 {
+		return e.err
+	}
+	rg := parquetRowGroup{rows: int64(len(logs))}
+	for _, c := range exportColumns {
+		var values bytes.Buffer
+		for _, l := range logs {
+			switch v := c.value(l).(type) {
+			case string:
+				values.Write(binary.LittleEndian.AppendUint32(nil, uint32(len(v))))
+				values.WriteString(v)
+			case int64:
+				values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v)))
+			case float64:
+				values.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(v)))
+			case time.Time:
+				values.Write(binary.LittleEndian.AppendUint64(nil, uint64(v.UnixMicro())))
+			}
+		}
+
+		// Required columns of a flat schema have no repetition or
+		// definition levels, so the page is the values alone
+		var header thriftWriter
+		header.begin()
+		header.i32(1, parquetDataPage)
+		header.i32(2, int32(values.Len()))
+		header.i32(3, int32(values.Len()))
+		header.structBegin(5)
+		header.i32(1, int32(len(logs)))
+		header.i32(2, parquetPlain)
+		header.i32(3, parquetRLE)
+		header.i32(4, parquetRLE)
+		header.end()
+		header.end()
+
+		chunk := parquetColumnChunk{offset: e.offset, size: int64(header.buf.Len() + values.Len())}
+		e.write(header.buf.Bytes())
+		e.write(values.Bytes())
+		rg.columns = append(rg.columns, chunk)
+	}
+	e.rowGroups = append(e.rowGroups, rg)
+	return e.err
+}
+
+// Close writes the file metadata and the closing magic
+func (e *parquetExport) Close() error {
+	if e.offset == 0 {
+		e.write([]byte(parquetMagic))
+	}
+
+	var rows int64
+	for _, rg := range e.rowGroups {
+		rows += rg.rows
+	}
+	var meta thriftWriter
+	meta.begin()
+	meta.i32(1, 1)
+	meta.listBegin(2, thriftStruct, len(exportColumns)+1)
+	meta.begin()
+	meta.binary(4, "request_log")
+	meta.i32(5, int32(len(exportColumns)))
+	meta.end()
+	for _, c := range exportColumns {
+		meta.begin()
+		meta.i32(1, parquetType(c.kind))
+		meta.i32(3, parquetRequired)
+		meta.binary(4, c.name)
+		switch c.kind {
+		case exportString:
+			meta.i32(6, parquetUTF8)
+		case exportTime:
+			meta.i32(6, parquetTimestampMicros)
+		}
+		meta.end()
+	}
+	meta.i64(3, rows)
+	meta.listBegin(4, thriftStruct, len(e.rowGroups))
+	for _, rg := range e.rowGroups {
+		var size int64
+		meta.begin()
+		meta.listBegin(1, thriftStruct, len(rg.columns))
+		for i, chunk := range rg.columns {
+			size += chunk.size
+			meta.begin()
+			meta.i64(2, chunk.offset)
+			meta.structBegin(3)
+			meta.i32(1, parquetType(exportColumns[i].kind))
+			meta.listBegin(2, thriftI32, 2)
+			meta.listI32(parquetPlain)
+			meta.listI32(parquetRLE)
+			meta.listBegin(3, thriftBinary, 1)
+			meta.listBinary(exportColumns[i].name)
+			meta.i32(4, 0) // Uncompressed
+			meta.i64(5, rg.rows)
+			meta.i64(6, chunk.size)
+			meta.i64(7, chunk.size)
+			meta.i64(9, chunk.offset)
+			meta.end()
+			meta.end()
+		}
+		meta.i64(2, size)
+		meta.i64(3, rg.rows)
+		meta.end()
+	}
+	meta.binary(6, "loom")
+	meta.end()
+
+	e.write(meta.buf.Bytes())
+	e.write(binary.LittleEndian.AppendUint32(nil, uint32(meta.buf.Len())))
+	e.write([]byte(parquetMagic))
+	return e.err
+}
+
+// parquetType is the physical type of an export column kind
+func parquetType(kind int) int32 {
+	switch kind {
+	case exportInt, exportTime:
+		return parquetInt64
+	case exportFloat:
+		return parquetDouble
+	default:
+		return parquetByteArray
+	}
+}
+
+// thriftWriter encodes structs in Thrift's compact protocol. Field IDs are
+// written as deltas from the previous field of the same struct, so every
+// struct, including list elements, is opened with begin (or structBegin
+// for a struct field) and closed with end.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+}
+
+func (w *thriftWriter) begin() {
+	w.last = append(w.last, 0)
+}
+
+func (w *thriftWriter) end() {
+	w.buf.WriteByte(0)
+	w.last = w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	last := &w.last[len(w.last)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.buf.Write(binary.AppendVarint(nil, int64(id)))
+	}
+	*last = id
+}
+
+func (w *thriftWriter) i32(id int16, v int32) {
+	w.field(id, thriftI32)
+	w.listI32(v)
+}
+
+func (w *thriftWriter) i64(id int16, v int64) {
+	w.field(id, thriftI64)
+	w.buf.Write(binary.AppendVarint(nil, v))
+}
+
+func (w *thriftWriter) binary(id int16, s string) {
+	w.field(id, thriftBinary)
+	w.listBinary(s)
+}
+
+func (w *thriftWriter) structBegin(id int16) {
+	w.field(id, thriftStruct)
+	w.begin()
+}
+
+// listBegin starts a list field of n elements, which follow as listI32,
+// listBinary or begin/end calls
+func (w *thriftWriter) listBegin(id int16, elem byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | elem)
+		return
+	}
+	w.buf.WriteByte(0xf0 | elem)
+	w.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+}
+
+func (w *thriftWriter) listI32(v int32) {
+	w.buf.Write(binary.AppendVarint(nil, int64(v)))
+}
+
+func (w *thriftWriter) listBinary(s string) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(s))))
+	w.buf.WriteString(s)
+}
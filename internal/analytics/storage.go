@@ -108,6 +108,11 @@ func (s *DatabaseStorage) GetLogs(ctx context.Context, filter *LogFilter) ([]*Re
 	`
 	args := []interface{}{}
 
+	if filter.ID != "" {
+		query += " AND id = ?"
+		args = append(args, filter.ID)
+	}
+
 	if filter.UserID != "" {
 		query += " AND user_id = ?"
 		args = append(args, filter.UserID)
@@ -128,7 +133,16 @@ func (s *DatabaseStorage) GetLogs(ctx context.Context, filter *LogFilter) ([]*Re
 		args = append(args, filter.EndTime)
 	}
 
-	query += " ORDER BY timestamp DESC"
+	if filter.After != nil {
+		query += " AND (timestamp > ? OR (timestamp = ? AND id > ?))"
+		args = append(args, filter.After.Timestamp, filter.After.Timestamp, filter.After.ID)
+	}
+
+	if filter.Ascending {
+		query += " ORDER BY timestamp ASC, id ASC"
+	} else {
+		query += " ORDER BY timestamp DESC"
+	}
 
 	if filter.Limit > 0 {
 		query += " LIMIT ?"
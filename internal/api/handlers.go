@@ -2,86 +2,12 @@ package api
 
 import (
 	"context"
+	"github.com/jordanhubbard/loom/internal/auth"
 	"github.com/jordanhubbard/loom/pkg/models"
 	"net/http"
 	"strings"
 )
 
-// handlePersonas handles GET /api/v1/personas
-func (s *Server) handlePersonas(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-		return
-	}
-
-	personas, err := s.app.GetPersonaManager().ListPersonas()
-	if err != nil {
-		s.respondError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// Load full persona details
-	fullPersonas := make([]*models.Persona, 0, len(personas))
-	for _, name := range personas {
-		persona, err := s.app.GetPersonaManager().LoadPersona(name)
-		if err != nil {
-			continue
-		}
-		fullPersonas = append(fullPersonas, persona)
-	}
-
-	s.respondJSON(w, http.StatusOK, fullPersonas)
-}
-
-// handlePersona handles GET/PUT /api/v1/personas/{name}
-func (s *Server) handlePersona(w http.ResponseWriter, r *http.Request) {
-	name := s.extractID(r.URL.Path, "/api/v1/personas")
-
-	switch r.Method {
-	case http.MethodGet:
-		persona, err := s.app.GetPersonaManager().LoadPersona(name)
-		if err != nil {
-			s.respondError(w, http.StatusNotFound, "Persona not found")
-			return
-		}
-		s.respondJSON(w, http.StatusOK, persona)
-
-	case http.MethodPut:
-		var persona models.Persona
-		if err := s.parseJSON(r, &persona); err != nil {
-			s.respondError(w, http.StatusBadRequest, "Invalid request body")
-			return
-		}
-
-		// Load existing persona
-		existing, err := s.app.GetPersonaManager().LoadPersona(name)
-		if err != nil {
-			s.respondError(w, http.StatusNotFound, "Persona not found")
-			return
-		}
-
-		// Update fields
-		persona.Name = existing.Name
-		persona.PersonaFile = existing.PersonaFile
-		persona.InstructionsFile = existing.InstructionsFile
-		persona.CreatedAt = existing.CreatedAt
-
-		// Save
-		if err := s.app.GetPersonaManager().SavePersona(&persona); err != nil {
-			s.respondError(w, http.StatusInternalServerError, err.Error())
-			return
-		}
-
-		// Invalidate cache
-		s.app.GetPersonaManager().InvalidateCache(name)
-
-		s.respondJSON(w, http.StatusOK, &persona)
-
-	default:
-		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
-	}
-}
-
 // handleAgents handles GET/POST /api/v1/agents
 func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -90,12 +16,7 @@ func (s *Server) handleAgents(w http.ResponseWriter, r *http.Request) {
 		s.respondJSON(w, http.StatusOK, agents)
 
 	case http.MethodPost:
-		var req struct {
-			Name        string `json:"name"`
-			PersonaName string `json:"persona_name"`
-			ProjectID   string `json:"project_id"`
-			ProviderID  string `json:"provider_id"`
-		}
+		var req models.SpawnAgentRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -257,17 +178,13 @@ func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		projects := s.app.GetProjectManager().ListProjects()
+		if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+			projects = s.app.GetProjectManager().ListProjectsInOrg(scope)
+		}
 		s.respondJSON(w, http.StatusOK, projects)
 
 	case http.MethodPost:
-		var req struct {
-			Name      string            `json:"name"`
-			GitRepo   string            `json:"git_repo"`
-			Branch    string            `json:"branch"`
-			BeadsPath string            `json:"beads_path"`
-			Context   map[string]string `json:"context"`
-			IsSticky  *bool             `json:"is_sticky"`
-		}
+		var req models.CreateProjectRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -278,17 +195,32 @@ func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		project, err := s.app.CreateProject(req.Name, req.GitRepo, req.Branch, req.BeadsPath, req.Context)
+		orgID, err := s.createOrg(r, req.OrgID)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		project, err := s.app.CreateProjectInOrg(orgID, req.Name, req.GitRepo, req.Branch, req.BeadsPath, req.Context)
 		if err != nil {
 			s.respondError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
+		updates := map[string]interface{}{}
 		if req.IsSticky != nil {
-			updates := map[string]interface{}{"is_sticky": *req.IsSticky}
+			updates["is_sticky"] = *req.IsSticky
+		}
+		if req.Compliance != nil {
+			updates["compliance"] = req.Compliance
+		}
+		if len(updates) > 0 {
 			if err := s.app.GetProjectManager().UpdateProject(project.ID, updates); err == nil {
 				s.app.PersistProject(project.ID)
 				project, _ = s.app.GetProjectManager().GetProject(project.ID)
 			}
+			if req.Compliance != nil {
+				s.auditComplianceChange(r, project.ID, req.Compliance)
+			}
 		}
 
 		s.respondJSON(w, http.StatusCreated, project)
@@ -311,6 +243,58 @@ func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
 			s.handleProjectFiles(w, r, id, parts[2:])
 			return
 		}
+		if action == "policy" {
+			s.handleProjectPolicy(w, r, id, parts[2:])
+			return
+		}
+		if action == "golden-prompts" {
+			s.handleProjectGoldenPrompts(w, r, id, parts[2:])
+			return
+		}
+		if action == "pr-reviews" {
+			s.handleProjectPRReviews(w, r, id, parts[2:])
+			return
+		}
+		if action == "judges" {
+			s.handleProjectJudges(w, r, id, parts[2:])
+			return
+		}
+		if action == "evaluations" {
+			s.handleProjectEvaluations(w, r, id, parts[2:])
+			return
+		}
+		if action == "issue-sync" {
+			s.handleProjectIssueSync(w, r, id, parts[2:])
+			return
+		}
+		if action == "knowledge" {
+			s.handleProjectKnowledge(w, r, id, parts[2:])
+			return
+		}
+		if action == "lessons" {
+			s.handleProjectLessons(w, r, id, parts[2:])
+			return
+		}
+		if action == "chat" {
+			s.handleProjectChat(w, r, id, parts[2:])
+			return
+		}
+		if action == "patches" {
+			s.handleProjectPatches(w, r, id, parts[2:])
+			return
+		}
+		if action == "onboarding-scan" {
+			s.handleProjectOnboardingScan(w, r, id)
+			return
+		}
+		if action == "dependency-updates" {
+			s.handleProjectDependencyUpdates(w, r, id)
+			return
+		}
+		if action == "language-servers" {
+			s.handleProjectLanguageServers(w, r, id)
+			return
+		}
 		s.handleProjectStateEndpoints(w, r, id, action)
 		return
 	}
@@ -326,17 +310,7 @@ func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
 		s.respondJSON(w, http.StatusOK, project)
 
 	case http.MethodPut:
-		var req struct {
-			Name        string            `json:"name"`
-			GitRepo     string            `json:"git_repo"`
-			Branch      string            `json:"branch"`
-			BeadsPath   string            `json:"beads_path"`
-			Context     map[string]string `json:"context"`
-			Status      string            `json:"status"`
-			GitStrategy *string           `json:"git_strategy"`
-			IsPerpetual *bool             `json:"is_perpetual"`
-			IsSticky    *bool             `json:"is_sticky"`
-		}
+		var req models.UpdateProjectRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -369,12 +343,21 @@ func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
 		if req.GitStrategy != nil {
 			updates["git_strategy"] = *req.GitStrategy
 		}
+		if req.SharedLessonsOptOut != nil {
+			updates["shared_lessons_opt_out"] = *req.SharedLessonsOptOut
+		}
+		if req.Compliance != nil {
+			updates["compliance"] = req.Compliance
+		}
 
 		if err := s.app.GetProjectManager().UpdateProject(id, updates); err != nil {
 			s.respondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
 		s.app.PersistProject(id)
+		if req.Compliance != nil {
+			s.auditComplianceChange(r, id, req.Compliance)
+		}
 		project, _ := s.app.GetProjectManager().GetProject(id)
 		s.respondJSON(w, http.StatusOK, project)
 
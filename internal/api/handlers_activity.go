@@ -5,14 +5,24 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jordanhubbard/loom/internal/activity"
 	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
+// activityListOptions pages the activity feed, newest first
+var activityListOptions = listOptions{defaultLimit: 100, sorts: []listSort{{"timestamp", "ts"}}, defaultDesc: true}
+
+// activityEventListOptions pages the events of an aggregated activity,
+// oldest first
+var activityEventListOptions = listOptions{defaultLimit: 100, sorts: []listSort{{"timestamp", "ts"}}}
+
 // handleGetActivityFeed handles GET requests for activity feed
-// GET /api/v1/activity-feed?project_id=xxx&event_type=xxx&limit=100
+// GET /api/v1/activity-feed?project_id=xxx&event_type=xxx&view=xxx&limit=100&cursor=xxx&sort=-timestamp
 func (s *Server) handleGetActivityFeed(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -26,8 +36,16 @@ func (s *Server) handleGetActivityFeed(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query parameters
+	lq, err := parseListQuery(r, activityListOptions)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	filters := activity.ActivityFilters{
-		Limit: 100, // Default limit
+		Limit:     lq.fetch(),
+		Offset:    lq.offset,
+		After:     lq.after,
+		Ascending: !lq.desc,
 	}
 
 	if projectID := r.URL.Query().Get("project_id"); projectID != "" {
@@ -58,18 +76,6 @@ func (s *Server) handleGetActivityFeed(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	if limit := r.URL.Query().Get("limit"); limit != "" {
-		if l, err := strconv.Atoi(limit); err == nil && l > 0 {
-			filters.Limit = l
-		}
-	}
-
-	if offset := r.URL.Query().Get("offset"); offset != "" {
-		if o, err := strconv.Atoi(offset); err == nil && o >= 0 {
-			filters.Offset = o
-		}
-	}
-
 	if aggregated := r.URL.Query().Get("aggregated"); aggregated != "" {
 		if agg, err := strconv.ParseBool(aggregated); err == nil {
 			filters.Aggregated = &agg
@@ -78,7 +84,6 @@ func (s *Server) handleGetActivityFeed(w http.ResponseWriter, r *http.Request) {
 
 	// Apply permission filtering based on authentication
 	userID := auth.GetUserIDFromRequest(r)
-	role := auth.GetRoleFromRequest(r)
 
 	// If auth is enabled and no user is authenticated, return unauthorized
 	if userID == "" && s.config.Security.EnableAuth {
@@ -86,30 +91,31 @@ func (s *Server) handleGetActivityFeed(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// If user is not admin, apply activity filtering
-	// For now, admins can see all activities, regular users see all public activities
-	// TODO: Enhance to filter by user's project membership once project-user relationships are implemented
-	if role != "admin" && s.config.Security.EnableAuth {
-		// In future, filter by projects the user has access to:
-		// userProjects := s.app.GetUserProjects(userID)
-		// if len(userProjects) > 0 {
-		//     filters.ProjectIDs = userProjects
-		// }
-		_ = userID // userID will be used for project filtering in future
+	// Callers confined to an organization see only its activity
+	filters.OrgID = auth.GetOrgIDFromRequest(r)
+
+	// A saved view's filters take the place of those given alongside it
+	if viewID := r.URL.Query().Get("view"); viewID != "" {
+		v, ok := s.visibleActivityView(w, r, activityMgr, viewID, userID)
+		if !ok {
+			return
+		}
+		v.Filters.Apply(&filters)
 	}
 
+	total, err := activityMgr.CountActivities(filters)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to count activities: %v", err))
+		return
+	}
 	activities, err := activityMgr.GetActivities(filters)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get activities: %v", err))
 		return
 	}
 
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"activities": activities,
-		"count":      len(activities),
-		"limit":      filters.Limit,
-		"offset":     filters.Offset,
-	})
+	page, p := pageOf(activities, lq, total, func(a *activity.Activity) []interface{} { return []interface{}{a.Timestamp, a.ID} })
+	s.respondList(w, r, "activities", page, p, nil)
 }
 
 // handleActivityFeedStream handles SSE endpoint for real-time activity feed
@@ -128,7 +134,7 @@ func (s *Server) handleActivityFeedStream(w http.ResponseWriter, r *http.Request
 
 	// Check authentication
 	userID := auth.GetUserIDFromRequest(r)
-	_ = userID // TODO: Use for permission filtering
+	orgScope := auth.GetOrgIDFromRequest(r)
 
 	// If auth is enabled and no user is authenticated, return unauthorized
 	if userID == "" && s.config.Security.EnableAuth {
@@ -188,15 +194,10 @@ func (s *Server) handleActivityFeedStream(w http.ResponseWriter, r *http.Request
 				continue
 			}
 
-			// Apply permission filtering
-			// TODO: Implement project-based filtering for non-admin users
-			// In future:
-			// role := auth.GetRoleFromRequest(r)
-			// if s.config.Security.EnableAuth && role != "admin" {
-			//     if !userHasAccessToProject(userID, activity.ProjectID) {
-			//         continue
-			//     }
-			// }
+			// Never stream another organization's activity
+			if orgScope != "" && org.Normalize(activity.OrgID) != orgScope {
+				continue
+			}
 
 			// Send activity to client
 			data, err := json.Marshal(activity)
@@ -219,3 +220,148 @@ func (s *Server) handleActivityFeedStream(w http.ResponseWriter, r *http.Request
 		}
 	}
 }
+
+// handleActivity handles /api/v1/activity-feed/{id}/events: the events an
+// aggregated activity stands for
+// GET /api/v1/activity-feed/{id}/events?limit=100&cursor=xxx&sort=-timestamp
+func (s *Server) handleActivity(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/activity-feed/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "events" {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	activityMgr := s.app.GetActivityManager()
+	if activityMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Activity manager not available")
+		return
+	}
+	if auth.GetUserIDFromRequest(r) == "" && s.config.Security.EnableAuth {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	lq, err := parseListQuery(r, activityEventListOptions)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a, err := activityMgr.GetActivity(parts[0])
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get activity: %v", err))
+		return
+	}
+	// Activities of other organizations are not found, not forbidden
+	if orgID := auth.GetOrgIDFromRequest(r); a == nil || (orgID != "" && a.OrgID != orgID) {
+		s.respondError(w, http.StatusNotFound, "Activity not found")
+		return
+	}
+
+	events, total, err := activityMgr.GetEvents(a.ID, activity.EventFilters{
+		Limit:      lq.fetch(),
+		Offset:     lq.offset,
+		After:      lq.after,
+		Descending: lq.desc,
+	})
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get activity events: %v", err))
+		return
+	}
+
+	page, p := pageOf(events, lq, total, func(e *activity.Event) []interface{} { return []interface{}{e.Timestamp, e.ID} })
+	s.respondList(w, r, "events", page, p, map[string]interface{}{"activity": a})
+}
+
+// handleActivityRollups handles GET /api/v1/activity-feed/rollups: the
+// summary rows expired activities were compacted into, newest first
+// GET /api/v1/activity-feed/rollups?project_id=xxx&event_type=xxx&since=xxx&until=xxx&limit=100
+func (s *Server) handleActivityRollups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	retention := s.app.GetActivityRetention()
+	if retention == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Activity retention not enabled")
+		return
+	}
+
+	q := r.URL.Query()
+	filters := database.ActivityRollupFilters{
+		OrgID:     auth.GetOrgIDFromRequest(r),
+		ProjectID: q.Get("project_id"),
+		EventType: q.Get("event_type"),
+		Limit:     100,
+	}
+	for name, dst := range map[string]*time.Time{"since": &filters.Since, "until": &filters.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s: %v", name, err))
+				return
+			}
+			*dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		filters.Limit = n
+	}
+
+	rollups, err := retention.Rollups(filters)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list activity rollups: %v", err))
+		return
+	}
+	if rollups == nil {
+		rollups = []*database.ActivityRollup{}
+	}
+	s.respondJSON(w, http.StatusOK, rollups)
+}
+
+// handleActivityRetention handles /api/v1/activity-feed/retention
+// GET  - The retention settings and the latest run
+// POST - Archive and compact expired activity now
+func (s *Server) handleActivityRetention(w http.ResponseWriter, r *http.Request) {
+	retention := s.app.GetActivityRetention()
+	if retention == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Activity retention not enabled")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cfg := retention.Config()
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"raw_retention":    cfg.RawRetention.String(),
+			"rollup_retention": cfg.RollupRetention.String(),
+			"interval":         cfg.Interval.String(),
+			"batch_size":       cfg.BatchSize,
+			"last_run":         retention.Last(),
+		})
+
+	case http.MethodPost:
+		report, err := retention.Run(r.Context())
+		if err != nil {
+			if report == nil {
+				s.respondError(w, http.StatusConflict, err.Error())
+				return
+			}
+			s.respondJSON(w, http.StatusInternalServerError, report)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, report)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/notifications"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// ActivityViewResponse is a saved activity view with the caller's
+// subscription to it, if any
+type ActivityViewResponse struct {
+	*activity.View
+	Subscription *notifications.ViewSubscription `json:"subscription,omitempty"`
+}
+
+// activityViewRequest is the body of a view create or update
+type activityViewRequest struct {
+	Name    string               `json:"name"`
+	Shared  bool                 `json:"shared"`
+	Filters activity.ViewFilters `json:"filters"`
+}
+
+// activityViews returns the activity manager and the caller, responding
+// with an error when either is missing
+func (s *Server) activityViews(w http.ResponseWriter, r *http.Request) (*activity.Manager, string, bool) {
+	activityMgr := s.app.GetActivityManager()
+	if activityMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Activity manager not available")
+		return nil, "", false
+	}
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, "", false
+	}
+	return activityMgr, user.ID, true
+}
+
+// visibleActivityView returns a view the caller may use, responding with
+// 404 when there is none
+func (s *Server) visibleActivityView(w http.ResponseWriter, r *http.Request, activityMgr *activity.Manager, id, userID string) (*activity.View, bool) {
+	v, err := activityMgr.GetView(id)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get activity view: %v", err))
+		return nil, false
+	}
+	if v == nil || v.OrgID != org.Normalize(auth.GetOrgIDFromRequest(r)) || !v.VisibleTo(userID) {
+		s.respondError(w, http.StatusNotFound, "Activity view not found")
+		return nil, false
+	}
+	return v, true
+}
+
+// handleActivityViews handles /api/v1/activity-views
+// GET  - The caller's views and those shared with them, by name
+// POST - Save a view
+func (s *Server) handleActivityViews(w http.ResponseWriter, r *http.Request) {
+	activityMgr, userID, ok := s.activityViews(w, r)
+	if !ok {
+		return
+	}
+	orgID := org.Normalize(auth.GetOrgIDFromRequest(r))
+
+	switch r.Method {
+	case http.MethodGet:
+		views, err := activityMgr.ListViews(orgID, userID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list activity views: %v", err))
+			return
+		}
+		subscribed := map[string]*notifications.ViewSubscription{}
+		if notificationMgr := s.app.GetNotificationManager(); notificationMgr != nil {
+			subs, err := notificationMgr.ViewSubscriptions(userID)
+			if err != nil {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			for _, sub := range subs {
+				subscribed[sub.ViewID] = sub
+			}
+		}
+		resp := make([]ActivityViewResponse, 0, len(views))
+		for _, v := range views {
+			resp = append(resp, ActivityViewResponse{View: v, Subscription: subscribed[v.ID]})
+		}
+		s.respondJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		var req activityViewRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		v := &activity.View{OrgID: orgID, OwnerID: userID, Name: req.Name, Shared: req.Shared, Filters: req.Filters}
+		if err := activityMgr.CreateView(v); err != nil {
+			s.respondActivityViewError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusCreated, ActivityViewResponse{View: v})
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleActivityView serves one view and the caller's subscription to it
+// GET    /api/v1/activity-views/{id}              - Get a view
+// PUT    /api/v1/activity-views/{id}              - Update a view (owner only)
+// DELETE /api/v1/activity-views/{id}              - Delete a view (owner only)
+// PUT    /api/v1/activity-views/{id}/subscription - Be notified of the view's activities
+// DELETE /api/v1/activity-views/{id}/subscription - Stop being notified
+func (s *Server) handleActivityView(w http.ResponseWriter, r *http.Request) {
+	activityMgr, userID, ok := s.activityViews(w, r)
+	if !ok {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/activity-views/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if parts[0] == "" {
+		s.respondError(w, http.StatusBadRequest, "View ID required")
+		return
+	}
+	v, ok := s.visibleActivityView(w, r, activityMgr, parts[0], userID)
+	if !ok {
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.handleActivityViewResource(w, r, activityMgr, v, userID)
+	case len(parts) == 2 && parts[1] == "subscription":
+		s.handleActivityViewSubscription(w, r, v, userID)
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *Server) handleActivityViewResource(w http.ResponseWriter, r *http.Request, activityMgr *activity.Manager, v *activity.View, userID string) {
+	if r.Method != http.MethodGet && v.OwnerID != userID {
+		s.respondError(w, http.StatusForbidden, "Only the owner may change an activity view")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.respondJSON(w, http.StatusOK, ActivityViewResponse{View: v})
+
+	case http.MethodPut:
+		var req activityViewRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		v.Name, v.Shared, v.Filters = req.Name, req.Shared, req.Filters
+		if err := activityMgr.UpdateView(v); err != nil {
+			s.respondActivityViewError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, ActivityViewResponse{View: v})
+
+	case http.MethodDelete:
+		if err := activityMgr.DeleteView(v.ID); err != nil {
+			s.respondActivityViewError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) handleActivityViewSubscription(w http.ResponseWriter, r *http.Request, v *activity.View, userID string) {
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		var req struct {
+			MinPriority string `json:"min_priority"`
+		}
+		if r.ContentLength != 0 {
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+		sub, err := notificationMgr.SubscribeView(v.ID, userID, req.MinPriority)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, sub)
+
+	case http.MethodDelete:
+		if err := notificationMgr.UnsubscribeView(v.ID, userID); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) respondActivityViewError(w http.ResponseWriter, err error) {
+	if errors.Is(err, activity.ErrInvalidView) {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.respondError(w, http.StatusInternalServerError, err.Error())
+}
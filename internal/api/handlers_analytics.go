@@ -4,8 +4,11 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jordanhubbard/loom/internal/analytics"
@@ -487,3 +490,410 @@ func exportLogsAsCSV(w http.ResponseWriter, logs []*analytics.RequestLog) {
 		})
 	}
 }
+
+// handleGetCostDrilldown handles GET /api/v1/analytics/anomalies/drilldown,
+// decomposing a cost spike by project, bead, agent and prompt template.
+// Without parameters it compares today against the previous seven days,
+// the same windows the anomaly alert uses.
+func (s *Server) handleGetCostDrilldown(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.analyticsLogger == nil {
+		http.Error(w, "Analytics unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID := auth.GetUserIDFromRequest(r)
+	if userID == "" && s.config.Security.EnableAuth {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	window, baseline := analytics.AnomalyWindow(time.Now())
+	query := r.URL.Query()
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"start_time", &window.Start},
+		{"end_time", &window.End},
+		{"baseline_start", &baseline.Start},
+		{"baseline_end", &baseline.End},
+	} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			http.Error(w, "Invalid "+p.name+": expected RFC3339", http.StatusBadRequest)
+			return
+		}
+		*p.dst = t
+	}
+	// A custom window without a custom baseline is compared against the
+	// seven days before it
+	if query.Get("start_time") != "" && query.Get("baseline_start") == "" && query.Get("baseline_end") == "" {
+		baseline = analytics.TimeRange{Start: window.Start.Add(-7 * 24 * time.Hour), End: window.Start}
+	}
+
+	top := 10
+	if topParam := query.Get("top"); topParam != "" {
+		if parsed, err := strconv.Atoi(topParam); err == nil && parsed > 0 {
+			if parsed > 100 {
+				parsed = 100
+			}
+			top = parsed
+		}
+	}
+
+	role := auth.GetRoleFromRequest(r)
+	if role == "admin" {
+		userID = query.Get("user_id")
+	}
+
+	drilldown, err := s.analyticsLogger.Drilldown(r.Context(), userID, window, baseline, top)
+	if err != nil {
+		if strings.Contains(err.Error(), "must be after") {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(drilldown); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// handleBeadCost returns what the requests made for a bead cost, with its
+// commits and pull requests
+// GET /api/v1/beads/{id}/cost
+func (s *Server) handleBeadCost(w http.ResponseWriter, r *http.Request, beadID string) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	cost, err := s.app.BeadCost(r.Context(), beadID)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			s.respondError(w, http.StatusNotFound, "Bead not found")
+		case strings.Contains(err.Error(), "unavailable"):
+			s.respondError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	s.respondJSON(w, http.StatusOK, cost)
+}
+
+// handleGetBeadCosts handles GET /api/v1/analytics/bead-costs, the cost of
+// each bead's requests in a period joined with its commits and pull
+// requests, as JSON or CSV. The period defaults to the last 30 days.
+func (s *Server) handleGetBeadCosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	query := r.URL.Query()
+	end := time.Now()
+	start := end.Add(-30 * 24 * time.Hour)
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"start_time", &start},
+		{"end_time", &end},
+	} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid "+p.name+": expected RFC3339")
+			return
+		}
+		*p.dst = t
+	}
+	if !end.After(start) {
+		s.respondError(w, http.StatusBadRequest, "end_time must be after start_time")
+		return
+	}
+
+	costs, err := s.app.BeadCosts(r.Context(), query.Get("project_id"), start, end)
+	if err != nil {
+		if strings.Contains(err.Error(), "unavailable") {
+			s.respondError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	// Callers confined to an organization see only its projects' beads
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		visible := costs[:0]
+		for _, c := range costs {
+			if s.app.ProjectOrg(c.ProjectID) == scope {
+				visible = append(visible, c)
+			}
+		}
+		costs = visible
+	}
+	if costs == nil {
+		costs = []*analytics.BeadCost{}
+	}
+
+	if query.Get("format") == "csv" {
+		exportBeadCostsAsCSV(w, costs)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, costs)
+}
+
+// exportBeadCostsAsCSV writes one row per bead, its pull requests as
+// number:cost pairs
+func exportBeadCostsAsCSV(w http.ResponseWriter, costs []*analytics.BeadCost) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"loom-bead-costs-"+time.Now().Format("2006-01-02")+".csv\"")
+
+	writer := csv.NewWriter(w)
+	defer writer.Flush()
+
+	_ = writer.Write([]string{
+		"Bead ID",
+		"Project ID",
+		"Cost (USD)",
+		"Tokens",
+		"Requests",
+		"Dispatches",
+		"Iterations",
+		"First Request",
+		"Last Request",
+		"Commits",
+		"Pull Requests",
+	})
+	for _, c := range costs {
+		prs := make([]string, len(c.PullRequests))
+		for i, pr := range c.PullRequests {
+			prs[i] = fmt.Sprintf("#%d:%.4f", pr.Number, pr.CostUSD)
+		}
+		_ = writer.Write([]string{
+			c.BeadID,
+			c.ProjectID,
+			fmt.Sprintf("%.4f", c.CostUSD),
+			fmt.Sprintf("%d", c.Tokens),
+			fmt.Sprintf("%d", c.Requests),
+			fmt.Sprintf("%d", c.Dispatches),
+			fmt.Sprintf("%d", c.Iterations),
+			c.FirstRequestAt.Format(time.RFC3339),
+			c.LastRequestAt.Format(time.RFC3339),
+			strings.Join(c.Commits, " "),
+			strings.Join(prs, " "),
+		})
+	}
+}
+
+// exportContentTypes are the media types of the log export formats
+var exportContentTypes = map[string]string{
+	analytics.ExportCSV:     "text/csv; charset=utf-8",
+	analytics.ExportParquet: "application/vnd.apache.parquet",
+}
+
+// handleStreamLogExport handles GET /api/v1/analytics/logs/export,
+// streaming request logs oldest first as CSV or Parquet. The response is
+// written in chunks as logs are read; an export that fails part way is
+// aborted, so the client sees a truncated response rather than a complete
+// one, and resumes with after set to the ID of the last row it received.
+// The X-Export-Rows trailer counts the rows of a complete export.
+func (s *Server) handleStreamLogExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.analyticsLogger == nil {
+		http.Error(w, "Analytics unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	userID := auth.GetUserIDFromRequest(r)
+	if userID == "" && s.config.Security.EnableAuth {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = analytics.ExportCSV
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		s.respondError(w, http.StatusBadRequest, "format must be csv or parquet")
+		return
+	}
+
+	filter := analytics.ExportFilter{
+		UserID:     userID,
+		ProviderID: query.Get("provider_id"),
+		ProjectID:  query.Get("project_id"),
+		AfterID:    query.Get("after"),
+	}
+	if auth.GetRoleFromRequest(r) == "admin" {
+		filter.UserID = query.Get("user_id")
+	}
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"start_time", &filter.StartTime},
+		{"end_time", &filter.EndTime},
+	} {
+		v := query.Get(p.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid "+p.name+": expected RFC3339")
+			return
+		}
+		*p.dst = t
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	// A large export outlasts the server's WriteTimeout
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", "attachment; filename=\"loom-logs-"+time.Now().Format("2006-01-02")+"."+format+"\"")
+	w.Header().Set("Trailer", "X-Export-Rows")
+
+	started := &startedWriter{w: w}
+	out, _ := analytics.NewExportWriter(format, started)
+	n, err := s.analyticsLogger.Export(r.Context(), filter, out, func() { _ = rc.Flush() })
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		if !started.written {
+			w.Header().Del("Content-Disposition")
+			w.Header().Del("Trailer")
+			if strings.Contains(err.Error(), "not found") {
+				s.respondError(w, http.StatusNotFound, err.Error())
+			} else {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		log.Printf("[Analytics] Log export failed after %d rows: %v", n, err)
+		panic(http.ErrAbortHandler)
+	}
+	w.Header().Set("X-Export-Rows", strconv.Itoa(n))
+}
+
+// startedWriter notes whether any of a response body has been written, after
+// which its status can no longer change
+type startedWriter struct {
+	w       io.Writer
+	written bool
+}
+
+func (sw *startedWriter) Write(p []byte) (int, error) {
+	sw.written = true
+	return sw.w.Write(p)
+}
+
+// Seconds between live metrics frames
+const (
+	liveMetricsDefaultInterval = 5
+	liveMetricsMaxInterval     = 60
+)
+
+// handleLiveMetrics handles GET /api/v1/analytics/live, an SSE stream of
+// rolling spend, token rate, cache hit rate and agent counts. A metrics
+// frame is sent on connect and then every interval seconds (default 5).
+// The figures are kept up to date as requests are logged, so a frame costs
+// no query however many dashboards are connected.
+func (s *Server) handleLiveMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.app == nil || s.app.GetLiveMetrics() == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Live metrics not available")
+		return
+	}
+
+	interval := liveMetricsDefaultInterval
+	if v := r.URL.Query().Get("interval"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > liveMetricsMaxInterval {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("interval must be between 1 and %d seconds", liveMetricsMaxInterval))
+			return
+		}
+		interval = n
+	}
+
+	// Disable write timeout for SSE - the server's WriteTimeout would kill
+	// long-running streams.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	send := func() {
+		data, err := json.Marshal(s.app.LiveMetrics(time.Now()))
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "event: metrics\n")
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+	send()
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			send()
+		}
+	}
+}
+
+// recordCacheLookup counts a response cache hit or miss into the live
+// metrics
+func (s *Server) recordCacheLookup(hit bool) {
+	if s.app == nil {
+		return
+	}
+	if m := s.app.GetLiveMetrics(); m != nil {
+		m.RecordCacheLookup(hit)
+	}
+}
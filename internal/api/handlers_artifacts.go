@@ -0,0 +1,135 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/artifacts"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// ResolvedPrompt is a prompt artifact with its messages filled in
+type ResolvedPrompt struct {
+	Digest      string                 `json:"digest"`
+	Model       string                 `json:"model"`
+	Temperature float64                `json:"temperature"`
+	Messages    []provider.ChatMessage `json:"messages"`
+}
+
+// artifactRecorder returns the artifact recorder, responding with an error
+// when artifacts are not being recorded
+func (s *Server) artifactRecorder(w http.ResponseWriter) (*artifacts.Recorder, bool) {
+	rec := s.app.GetArtifactRecorder()
+	if rec == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Artifact store not available")
+		return nil, false
+	}
+	return rec, true
+}
+
+// handleDispatchArtifacts lists or verifies the artifacts of one dispatch of
+// a bead; rest is the path after .../artifacts
+func (s *Server) handleDispatchArtifacts(w http.ResponseWriter, r *http.Request, beadID, dispatchID string, rest []string) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	rec, ok := s.artifactRecorder(w)
+	if !ok {
+		return
+	}
+
+	list, err := rec.List(dispatchID)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	owned := make([]*artifacts.Artifact, 0, len(list))
+	for _, a := range list {
+		if a.BeadID == beadID {
+			owned = append(owned, a)
+		}
+	}
+	if len(list) > 0 && len(owned) == 0 {
+		s.respondError(w, http.StatusNotFound, "Dispatch not found for this bead")
+		return
+	}
+
+	switch {
+	case len(rest) == 0:
+		s.respondJSON(w, http.StatusOK, owned)
+	case len(rest) == 1 && rest[0] == "verify":
+		result, err := rec.Verify(dispatchID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, result)
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleArtifact serves stored artifact content by digest
+// GET /api/v1/artifacts/{digest}        - Raw content, verified against the digest
+// GET /api/v1/artifacts/{digest}/prompt - A prompt artifact with its messages resolved
+func (s *Server) handleArtifact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/artifacts/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	digest := parts[0]
+	if !artifacts.ValidDigest(digest) {
+		s.respondError(w, http.StatusBadRequest, "Invalid artifact digest")
+		return
+	}
+	rec, ok := s.artifactRecorder(w)
+	if !ok {
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		content, err := rec.Get(digest)
+		if err != nil {
+			s.respondArtifactError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+		w.Header().Set("X-Artifact-Digest", digest)
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(content)
+
+	case len(parts) == 2 && parts[1] == "prompt":
+		manifest, messages, err := rec.ResolvePrompt(digest)
+		if err != nil {
+			s.respondArtifactError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, ResolvedPrompt{
+			Digest:      digest,
+			Model:       manifest.Model,
+			Temperature: manifest.Temperature,
+			Messages:    messages,
+		})
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+func (s *Server) respondArtifactError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		s.respondError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "not a prompt"):
+		s.respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,416 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/backup"
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/evaluation"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/internal/policy"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+	"github.com/jordanhubbard/loom/internal/reports"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// requireAuditAdmin rejects non-admin callers; the audit log is admin-only
+func (s *Server) requireAuditAdmin(w http.ResponseWriter, r *http.Request) (*audit.Logger, bool) {
+	if s.app == nil || s.app.GetAuditLogger() == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Audit log not initialized")
+		return nil, false
+	}
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return nil, false
+	}
+	if user.Role != "admin" {
+		s.respondError(w, http.StatusForbidden, "Forbidden: admin access required")
+		return nil, false
+	}
+	return s.app.GetAuditLogger(), true
+}
+
+// parseAuditFilter builds a filter from actor, action, resource, project_id,
+// outcome, since, until (RFC3339), after_seq and limit query parameters
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Actor:     q.Get("actor"),
+		Action:    q.Get("action"),
+		Resource:  q.Get("resource"),
+		ProjectID: q.Get("project_id"),
+		Outcome:   q.Get("outcome"),
+	}
+	var err error
+	if v := q.Get("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("after_seq"); v != "" {
+		if f.AfterSeq, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return f, err
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		if f.Limit, err = strconv.Atoi(v); err != nil {
+			return f, err
+		}
+	}
+	return f, nil
+}
+
+// handleAuditLog handles GET /api/v1/audit
+func (s *Server) handleAuditLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	logger, ok := s.requireAuditAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	f, err := parseAuditFilter(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid filter: "+err.Error())
+		return
+	}
+	if f.Limit <= 0 || f.Limit > 1000 {
+		f.Limit = 100
+	}
+	entries, err := logger.Query(f)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if entries == nil {
+		entries = []*audit.Entry{}
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{"entries": entries})
+}
+
+// handleAuditVerify handles GET /api/v1/audit/verify, which recomputes the
+// hash chain and reports the first broken entry
+func (s *Server) handleAuditVerify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	logger, ok := s.requireAuditAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	result, err := logger.Verify()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, result)
+}
+
+// handleAuditExport handles GET /api/v1/audit/export, streaming matching
+// entries as JSONL for compliance archives
+func (s *Server) handleAuditExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	logger, ok := s.requireAuditAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	f, err := parseAuditFilter(r)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid filter: "+err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", "attachment; filename=audit-log.jsonl")
+	if _, err := logger.ExportJSONL(w, f); err != nil {
+		// Headers are already sent; the truncated export is all we can signal
+		return
+	}
+}
+
+// auditComplianceChange records a change to a project's compliance constraints
+func (s *Server) auditComplianceChange(r *http.Request, projectID string, c *models.ComplianceConstraints) {
+	ev := audit.Event{
+		Actor:     "anonymous",
+		Action:    "project.compliance.update",
+		Resource:  projectID,
+		ProjectID: projectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"required_provider_tags": c.RequiredProviderTags,
+			"no_body_logging":        c.NoBodyLogging,
+			"require_redaction":      c.RequireRedaction,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditEventWebhook records a change to an outbound event webhook
+func (s *Server) auditEventWebhook(r *http.Request, action string, sub *eventhooks.Subscription) {
+	ev := audit.Event{
+		Actor:    "anonymous",
+		Action:   action,
+		Resource: sub.ID,
+		Outcome:  audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"url":         sub.URL,
+			"event_types": sub.EventTypes,
+			"project_ids": sub.ProjectIDs,
+			"enabled":     sub.Enabled,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditReportSchedule records a change to a scheduled report
+func (s *Server) auditReportSchedule(r *http.Request, action string, sched *reports.Schedule) {
+	targets := make([]string, 0, len(sched.Destinations))
+	for _, d := range sched.Destinations {
+		targets = append(targets, d.Type)
+	}
+	ev := audit.Event{
+		Actor:    "anonymous",
+		Action:   action,
+		Resource: sched.ID,
+		Outcome:  audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"org_id":       sched.OrgID,
+			"report_type":  sched.ReportType,
+			"format":       sched.Format,
+			"frequency":    sched.Frequency,
+			"destinations": targets,
+			"enabled":      sched.Enabled,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditOrganization records a change to an organization
+func (s *Server) auditOrganization(r *http.Request, action string, o *org.Organization) {
+	ev := audit.Event{
+		Actor:    "anonymous",
+		Action:   action,
+		Resource: o.ID,
+		Outcome:  audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"name":             o.Name,
+			"daily_budget_usd": o.DailyBudgetUSD,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditProjectTemplate records a change to a project template, or a
+// project made from one
+func (s *Server) auditProjectTemplate(r *http.Request, action string, t *projecttemplates.Template, projectID string) {
+	ev := audit.Event{
+		Actor:     "anonymous",
+		Action:    action,
+		Resource:  t.ID,
+		ProjectID: projectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"org_id":        t.OrgID,
+			"name":          t.Name,
+			"git_repo":      t.GitRepo,
+			"personas":      t.Personas,
+			"providers":     t.Providers,
+			"beads":         len(t.Beads),
+			"notifications": len(t.Notifications),
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditGoldenPrompt records a change to a project's golden prompt suite
+func (s *Server) auditGoldenPrompt(r *http.Request, action string, c *goldenprompts.Case) {
+	ev := audit.Event{
+		Actor:     "anonymous",
+		Action:    action,
+		Resource:  c.ID,
+		ProjectID: c.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"name":         c.Name,
+			"expectations": len(c.Expectations),
+			"providers":    c.ProviderIDs,
+			"enabled":      c.Enabled,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditJudge records a change to a project's judges
+func (s *Server) auditJudge(r *http.Request, action string, j *evaluation.Judge) {
+	ev := audit.Event{
+		Actor:     "anonymous",
+		Action:    action,
+		Resource:  j.ID,
+		ProjectID: j.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"name":     j.Name,
+			"persona":  j.Persona,
+			"criteria": len(j.Criteria),
+			"enabled":  j.Enabled,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditConfigChange records a configuration change made through the API
+func (s *Server) auditConfigChange(r *http.Request, source string, err error) {
+	ev := audit.Event{
+		Actor:   "anonymous",
+		Action:  "config.update",
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]interface{}{"source": source},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	if err != nil {
+		ev.Outcome = audit.OutcomeFailure
+		ev.Details["error"] = err.Error()
+	}
+	audit.Record(ev)
+}
+
+// auditLogLevels records a runtime change to the log levels
+func (s *Server) auditLogLevels(r *http.Request, req LogLevelsRequest) {
+	ev := audit.Event{
+		Actor:   "anonymous",
+		Action:  "logging.levels.update",
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]interface{}{"default": req.Default, "modules": req.Modules},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditPolicyPublish records a newly published project policy version
+func (s *Server) auditPolicyPublish(r *http.Request, v *policy.Version) {
+	ev := audit.Event{
+		Actor:     "anonymous",
+		Action:    "policy.publish",
+		Resource:  v.ProjectID,
+		ProjectID: v.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"version": v.Version,
+			"rules":   len(v.Document.Rules),
+			"comment": v.Comment,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditPersona records a published, rolled back or deleted persona
+// definition
+func (s *Server) auditPersona(r *http.Request, action, name string, v *persona.Version) {
+	ev := audit.Event{
+		Actor:    "anonymous",
+		Action:   action,
+		Resource: name,
+		Outcome:  audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"version":          v.Version,
+			"rolled_back_from": v.RolledBackFrom,
+			"allowed_actions":  v.Definition.AllowedActions,
+			"model_tier":       v.Definition.ModelTier,
+			"comment":          v.Comment,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditBackup records a backup taken or deleted through the API
+func (s *Server) auditBackup(r *http.Request, action string, b *backup.Backup) {
+	ev := audit.Event{
+		Actor:    "anonymous",
+		Action:   action,
+		Resource: b.ID,
+		Outcome:  audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"trigger":        b.Trigger,
+			"database_type":  b.DatabaseType,
+			"schema_version": b.SchemaVersion,
+			"size_bytes":     b.SizeBytes,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
+
+// auditBenchmark records a benchmark run started through the API
+func (s *Server) auditBenchmark(r *http.Request, run *benchmark.Run) {
+	providers := make([]string, 0, len(run.Providers))
+	for _, p := range run.Providers {
+		providers = append(providers, p.ProviderID)
+	}
+	ev := audit.Event{
+		Actor:    "anonymous",
+		Action:   "benchmark.run",
+		Resource: run.ID,
+		Outcome:  audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"providers": providers,
+			"passed":    run.Passed,
+			"failed":    run.Failed,
+		},
+	}
+	if user := s.getUserFromContext(r); user != nil {
+		ev.Actor = user.ID
+	}
+	audit.Record(ev)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/backup"
+)
+
+// backups returns the backup manager, responding with an error when there
+// is none
+func (s *Server) backups(w http.ResponseWriter) (*backup.Manager, bool) {
+	mgr := s.app.GetBackups()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Backups not available")
+		return nil, false
+	}
+	return mgr, true
+}
+
+// handleBackups handles GET/POST /api/v1/backups: list backups, newest
+// first, or take one now
+func (s *Server) handleBackups(w http.ResponseWriter, r *http.Request) {
+	mgr, ok := s.backups(w)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := mgr.List()
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if list == nil {
+			list = []*backup.Backup{}
+		}
+		s.respondJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		createdBy := ""
+		if user := s.getUserFromContext(r); user != nil {
+			createdBy = user.ID
+		}
+		b, err := mgr.Create(r.Context(), backup.TriggerManual, createdBy)
+		if err != nil {
+			s.respondBackupError(w, err)
+			return
+		}
+		s.auditBackup(r, "backup.create", b)
+		s.respondJSON(w, http.StatusCreated, b)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleBackup serves one backup. Restores are not offered over HTTP: they
+// replace the database under the running server, so they are run with
+// `loom backup restore` while it is stopped.
+// GET    /api/v1/backups/{id}        - Get a backup
+// DELETE /api/v1/backups/{id}        - Delete a backup
+// POST   /api/v1/backups/{id}/verify - Check its checksums and database dump
+func (s *Server) handleBackup(w http.ResponseWriter, r *http.Request) {
+	mgr, ok := s.backups(w)
+	if !ok {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/backups/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	id := parts[0]
+	if id == "" {
+		s.respondError(w, http.StatusBadRequest, "Backup ID required")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		switch r.Method {
+		case http.MethodGet:
+			b, err := mgr.Get(id)
+			if err != nil {
+				s.respondBackupError(w, err)
+				return
+			}
+			s.respondJSON(w, http.StatusOK, b)
+
+		case http.MethodDelete:
+			b, err := mgr.Get(id)
+			if err != nil {
+				s.respondBackupError(w, err)
+				return
+			}
+			if err := mgr.Delete(id); err != nil {
+				s.respondBackupError(w, err)
+				return
+			}
+			s.auditBackup(r, "backup.delete", b)
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	case len(parts) == 2 && parts[1] == "verify":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		v, err := mgr.Verify(r.Context(), id)
+		if err != nil {
+			s.respondBackupError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, v)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// respondBackupError maps backup errors to status codes
+func (s *Server) respondBackupError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.Contains(msg, "already running"):
+		s.respondError(w, http.StatusConflict, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
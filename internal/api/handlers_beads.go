@@ -6,9 +6,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/beads"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
+// beadListOptions pages GET /api/v1/beads. Without limit or cursor every
+// matching bead is returned, as the web UI expects.
+var beadListOptions = listOptions{sorts: []listSort{
+	{"priority", "is"}, {"created_at", "ts"}, {"updated_at", "ts"}, {"title", "ss"}, {"status", "ss"},
+}}
+
 // handleBeads handles GET/POST /api/v1/beads
 func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
@@ -47,25 +55,33 @@ func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		beads, err := s.app.GetBeadsManager().ListBeads(filters)
+		lq, err := parseListQuery(r, beadListOptions)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+			filters["org_id"] = scope
+		}
+
+		list, total, err := s.app.GetBeadsManager().ListBeadsPage(filters, beads.PageQuery{
+			Sort:   lq.sort,
+			Desc:   lq.desc,
+			After:  lq.after,
+			Offset: lq.offset,
+			Limit:  lq.fetch(),
+		})
 		if err != nil {
 			s.respondError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		s.respondJSON(w, http.StatusOK, beads)
+		page, p := pageOf(list, lq, total, func(b *models.Bead) []interface{} { return beads.SortKey(b, lq.sort) })
+		s.respondList(w, r, "", page, p, nil)
 
 	case http.MethodPost:
-		var req struct {
-			Type        string            `json:"type"`
-			Title       string            `json:"title"`
-			Description string            `json:"description"`
-			Priority    int               `json:"priority"`
-			ProjectID   string            `json:"project_id"`
-			Parent      string            `json:"parent"`
-			Tags        []string          `json:"tags"`
-			Context     map[string]string `json:"context"`
-		}
+		var req models.CreateBeadRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -97,11 +113,21 @@ func (s *Server) handleBeads(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleBead handles GET/PATCH /api/v1/beads/{id} and POST /api/v1/beads/{id}/claim
+// and /verify
 func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/v1/beads/")
 	parts := strings.Split(path, "/")
 	id := parts[0]
 
+	// Callers confined to an organization reach only its projects' beads
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		bead, err := s.app.GetBeadsManager().GetBead(id)
+		if err != nil || s.app.ProjectOrg(bead.ProjectID) != scope {
+			s.respondError(w, http.StatusNotFound, "Bead not found")
+			return
+		}
+	}
+
 	// Handle /conversation endpoint
 	if len(parts) > 1 && parts[1] == "conversation" {
 		s.handleBeadConversation(w, r)
@@ -114,6 +140,18 @@ func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Handle /dispatches endpoints (history and rollback)
+	if len(parts) > 1 && parts[1] == "dispatches" {
+		s.handleBeadDispatches(w, r)
+		return
+	}
+
+	// Handle /undo endpoint (revert everything the bead did)
+	if len(parts) > 1 && parts[1] == "undo" {
+		s.handleBeadUndo(w, r, id)
+		return
+	}
+
 	// Handle /claim endpoint
 	if len(parts) > 1 && parts[1] == "claim" {
 		if r.Method != http.MethodPost {
@@ -121,9 +159,7 @@ func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var req struct {
-			AgentID string `json:"agent_id"`
-		}
+		var req models.ClaimBeadRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -143,7 +179,49 @@ func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		s.respondJSON(w, http.StatusOK, map[string]string{"status": "claimed"})
+		s.respondJSON(w, http.StatusOK, models.StatusResponse{Status: "claimed"})
+		return
+	}
+
+	// Handle /verify endpoint
+	if len(parts) > 1 && parts[1] == "verify" {
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+
+		// A test run can outlast the server's WriteTimeout
+		_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+		result, err := s.app.VerifyBead(r.Context(), id)
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				s.respondError(w, http.StatusNotFound, err.Error())
+			} else {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+
+		s.respondJSON(w, http.StatusOK, result)
+		return
+	}
+
+	// Handle /ci endpoint
+	if len(parts) > 1 && parts[1] == "ci" {
+		s.handleBeadCI(w, r, id)
+		return
+	}
+
+	// Handle /cost endpoint
+	if len(parts) > 1 && parts[1] == "cost" {
+		s.handleBeadCost(w, r, id)
+		return
+	}
+
+	// Handle /plan endpoints (plan mode review)
+	if len(parts) > 1 && parts[1] == "plan" {
+		s.handleBeadPlan(w, r, id, parts[2:])
 		return
 	}
 
@@ -210,22 +288,7 @@ func (s *Server) handleBead(w http.ResponseWriter, r *http.Request) {
 		s.respondJSON(w, http.StatusOK, bead)
 
 	case http.MethodPatch:
-		var req struct {
-			Title       *string           `json:"title"`
-			Type        *string           `json:"type"`
-			Status      *string           `json:"status"`
-			Priority    *int              `json:"priority"`
-			ProjectID   *string           `json:"project_id"`
-			AssignedTo  *string           `json:"assigned_to"`
-			Description *string           `json:"description"`
-			Parent      *string           `json:"parent"`
-			Tags        *[]string         `json:"tags"`
-			BlockedBy   *[]string         `json:"blocked_by"`
-			Blocks      *[]string         `json:"blocks"`
-			RelatedTo   *[]string         `json:"related_to"`
-			Children    *[]string         `json:"children"`
-			Context     map[string]string `json:"context"`
-		}
+		var req models.UpdateBeadRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -330,11 +393,7 @@ func (s *Server) handleDecision(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		var req struct {
-			DeciderID string `json:"decider_id"`
-			Decision  string `json:"decision"`
-			Rationale string `json:"rationale"`
-		}
+		var req models.DecideRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -345,12 +404,17 @@ func (s *Server) handleDecision(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// An authenticated caller decides as themselves, whatever the body says
+		if userID := auth.GetUserIDFromRequest(r); userID != "" {
+			req.DeciderID = "user-" + userID
+		}
+
 		if err := s.app.MakeDecision(id, req.DeciderID, req.Decision, req.Rationale); err != nil {
 			s.respondError(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
-		s.respondJSON(w, http.StatusOK, map[string]string{"status": "decided"})
+		s.respondJSON(w, http.StatusOK, models.StatusResponse{Status: "decided"})
 		return
 	}
 
@@ -384,12 +448,7 @@ func (s *Server) handleFileLocks(w http.ResponseWriter, r *http.Request) {
 		s.respondJSON(w, http.StatusOK, locks)
 
 	case http.MethodPost:
-		var req struct {
-			FilePath  string `json:"file_path"`
-			ProjectID string `json:"project_id"`
-			AgentID   string `json:"agent_id"`
-			BeadID    string `json:"bead_id"`
-		}
+		var req models.FileLockRequest
 		if err := s.parseJSON(r, &req); err != nil {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
@@ -402,7 +461,7 @@ func (s *Server) handleFileLocks(w http.ResponseWriter, r *http.Request) {
 
 		lock, err := s.app.RequestFileAccess(req.ProjectID, req.FilePath, req.AgentID, req.BeadID)
 		if err != nil {
-			if strings.Contains(err.Error(), "already locked") {
+			if strings.Contains(err.Error(), "already locked") || strings.Contains(err.Error(), "deadlock") {
 				s.respondError(w, http.StatusConflict, err.Error())
 			} else {
 				s.respondError(w, http.StatusInternalServerError, err.Error())
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/benchmark"
+)
+
+// benchmarkRunsDefaultLimit is how many runs are listed without ?limit
+const benchmarkRunsDefaultLimit = 20
+
+// BenchmarkRequest selects the providers a benchmark run covers
+type BenchmarkRequest struct {
+	ProviderIDs []string `json:"provider_ids,omitempty"` // Empty benchmarks every active provider
+}
+
+// handleBenchmarks serves provider benchmarks
+// GET  /api/v1/benchmarks/tasks           - The golden tasks providers run
+// GET  /api/v1/benchmarks/runs            - Recent runs, newest first
+// POST /api/v1/benchmarks/runs            - Benchmark providers now
+// GET  /api/v1/benchmarks/runs/{run_id}   - One run with every result
+func (s *Server) handleBenchmarks(w http.ResponseWriter, r *http.Request) {
+	mgr := s.app.GetBenchmarks()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Benchmarks not available")
+		return
+	}
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/benchmarks"), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case len(parts) == 1 && parts[0] == "tasks":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		s.respondJSON(w, http.StatusOK, mgr.Tasks())
+
+	case len(parts) == 1 && parts[0] == "runs":
+		switch r.Method {
+		case http.MethodGet:
+			limit := benchmarkRunsDefaultLimit
+			if v := r.URL.Query().Get("limit"); v != "" {
+				n, err := strconv.Atoi(v)
+				if err != nil || n <= 0 {
+					s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+					return
+				}
+				limit = n
+			}
+			runs, err := mgr.Runs(limit)
+			if err != nil {
+				s.respondBenchmarkError(w, err)
+				return
+			}
+			if runs == nil {
+				runs = []*benchmark.Run{}
+			}
+			s.respondJSON(w, http.StatusOK, runs)
+
+		case http.MethodPost:
+			var req BenchmarkRequest
+			if r.ContentLength != 0 {
+				if err := s.parseJSON(r, &req); err != nil {
+					s.respondError(w, http.StatusBadRequest, "Invalid request body")
+					return
+				}
+			}
+			createdBy := ""
+			if user := s.getUserFromContext(r); user != nil {
+				createdBy = user.ID
+			}
+			run, err := mgr.Run(r.Context(), benchmark.TriggerManual, req.ProviderIDs, createdBy)
+			if err != nil {
+				s.respondBenchmarkError(w, err)
+				return
+			}
+			s.auditBenchmark(r, run)
+			s.respondJSON(w, http.StatusOK, run)
+
+		default:
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	case len(parts) == 2 && parts[0] == "runs":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		run, err := mgr.GetRun(parts[1])
+		if err != nil {
+			s.respondBenchmarkError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, run)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// respondBenchmarkError maps benchmark errors to status codes
+func (s *Server) respondBenchmarkError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.Contains(msg, "already running"):
+		s.respondError(w, http.StatusConflict, msg)
+	case strings.HasPrefix(msg, "invalid"):
+		s.respondError(w, http.StatusBadRequest, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
@@ -66,6 +66,7 @@ func (s *Server) handleGetCacheConfig(w http.ResponseWriter, r *http.Request) {
 		"max_size":       cacheConfig.MaxSize,
 		"max_memory_mb":  cacheConfig.MaxMemoryMB,
 		"cleanup_period": cacheConfig.CleanupPeriod.String(),
+		"compression":    cacheConfig.Compression,
 	}); err != nil {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
@@ -169,6 +170,45 @@ func (s *Server) handleInvalidateCache(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleCacheWarm handles GET and POST /api/v1/cache/warm. GET returns the
+// report of the last warming pass; POST warms the cache now.
+func (s *Server) handleCacheWarm(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Authentication required (admin only)
+	role := auth.GetRoleFromRequest(r)
+	if role != "admin" {
+		http.Error(w, "Forbidden: admin access required", http.StatusForbidden)
+		return
+	}
+
+	if s.cacheWarmer == nil {
+		http.Error(w, "Cache warming not enabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	report := s.cacheWarmer.Last()
+	if r.Method == http.MethodPost {
+		var err error
+		if report, err = s.cacheWarmer.Warm(r.Context()); err != nil {
+			http.Error(w, "Cache warming failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if report == nil {
+		http.Error(w, "Cache not warmed yet", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
 // CacheToCacheConfig converts cache.Config to a format suitable for API responses
 func CacheToCacheConfig(c *cache.Config) map[string]interface{} {
 	return map[string]interface{}{
@@ -177,5 +217,6 @@ func CacheToCacheConfig(c *cache.Config) map[string]interface{} {
 		"max_size":       c.MaxSize,
 		"max_memory_mb":  c.MaxMemoryMB,
 		"cleanup_period": c.CleanupPeriod.String(),
+		"compression":    c.Compression,
 	}
 }
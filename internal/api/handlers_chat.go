@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/chat"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// chatTimeout bounds the answer to one chat message, tool calls included
+const chatTimeout = 5 * time.Minute
+
+// handleProjectChat serves the read-only chat with a project's agent
+// POST   /api/v1/projects/{id}/chat               - Ask a question, starting a session without session_id
+// GET    /api/v1/projects/{id}/chat/{session_id}  - The session's transcript
+// DELETE /api/v1/projects/{id}/chat/{session_id}  - End the session
+func (s *Server) handleProjectChat(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	userID := auth.GetUserIDFromRequest(r)
+
+	switch {
+	case len(parts) == 0:
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		s.askChat(w, r, projectID, userID)
+
+	case len(parts) == 1:
+		session, err := s.app.ChatSession(projectID, parts[0], userID)
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			s.respondJSON(w, http.StatusOK, session.Snapshot())
+		case http.MethodDelete:
+			if err := s.app.EndChat(projectID, session.ID, userID); err != nil {
+				s.respondError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// askChat answers a chat message as JSON, or as server-sent events when
+// the request asks to stream: a session event, then chunk, tool_call and
+// tool_result events as the agent works, a message event with its answer
+// and a done event with the whole reply
+func (s *Server) askChat(w http.ResponseWriter, r *http.Request, projectID, userID string) {
+	var req models.ChatRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if strings.TrimSpace(req.Message) == "" {
+		s.respondError(w, http.StatusBadRequest, "message is required")
+		return
+	}
+
+	var session *chat.Session
+	var err error
+	if req.SessionID != "" {
+		session, err = s.app.ChatSession(projectID, req.SessionID, userID)
+	} else {
+		session, err = s.app.StartChat(projectID, userID)
+	}
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), chatTimeout)
+	defer cancel()
+
+	if !req.Stream && !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		reply, err := s.app.AskChat(ctx, session, req.Message, nil)
+		if err != nil {
+			s.respondError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, reply)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.respondError(w, http.StatusInternalServerError, "Streaming not supported")
+		return
+	}
+	// The server's write timeout would cut off long answers
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	send := func(event string, v interface{}) {
+		data, _ := json.Marshal(v)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	}
+	send("session", map[string]string{"session_id": session.ID})
+
+	reply, err := s.app.AskChat(ctx, session, req.Message, func(e chat.Event) {
+		send(e.Type, e)
+	})
+	if err != nil {
+		send("error", map[string]string{"error": err.Error()})
+		return
+	}
+	send("done", reply)
+}
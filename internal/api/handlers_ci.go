@@ -0,0 +1,185 @@
+package api
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/jordanhubbard/loom/internal/cistatus"
+)
+
+// gitHubCheckRunPayload is the part of a GitHub check_run event CI tracking
+// reads
+type gitHubCheckRunPayload struct {
+	CheckRun struct {
+		Name       string `json:"name"`
+		HeadSHA    string `json:"head_sha"`
+		Status     string `json:"status"`
+		Conclusion string `json:"conclusion"`
+		HTMLURL    string `json:"html_url"`
+		CheckSuite struct {
+			HeadBranch string `json:"head_branch"`
+		} `json:"check_suite"`
+	} `json:"check_run"`
+	Repository *GitHubRepository `json:"repository,omitempty"`
+}
+
+// gitHubStatusPayload is the part of a GitHub status event CI tracking
+// reads
+type gitHubStatusPayload struct {
+	SHA       string `json:"sha"`
+	State     string `json:"state"`
+	Context   string `json:"context"`
+	TargetURL string `json:"target_url"`
+	Branches  []struct {
+		Name string `json:"name"`
+	} `json:"branches"`
+	Repository *GitHubRepository `json:"repository,omitempty"`
+}
+
+// gitLabPipelinePayload is the part of a GitLab pipeline event CI tracking
+// reads
+type gitLabPipelinePayload struct {
+	ObjectAttributes struct {
+		Ref    string `json:"ref"`
+		SHA    string `json:"sha"`
+		Status string `json:"status"`
+		URL    string `json:"url"`
+	} `json:"object_attributes"`
+	Project struct {
+		PathWithNamespace string `json:"path_with_namespace"`
+	} `json:"project"`
+	Builds []struct {
+		Name   string `json:"name"`
+		Status string `json:"status"`
+	} `json:"builds"`
+}
+
+// handleGitHubCIEvent records a check_run or status event on the branch it
+// ran for
+func (s *Server) handleGitHubCIEvent(w http.ResponseWriter, r *http.Request, eventType string, body []byte) {
+	var repository, branch, sha string
+	var check cistatus.Check
+	switch eventType {
+	case "check_run":
+		var payload gitHubCheckRunPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Repository == nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+		run := payload.CheckRun
+		repository, branch, sha = payload.Repository.FullName, run.CheckSuite.HeadBranch, run.HeadSHA
+		check = cistatus.Check{Name: run.Name, State: cistatus.GitHubCheckState(run.Status, run.Conclusion), URL: run.HTMLURL}
+	default:
+		var payload gitHubStatusPayload
+		if err := json.Unmarshal(body, &payload); err != nil || payload.Repository == nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid JSON payload")
+			return
+		}
+		if len(payload.Branches) == 0 {
+			s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+			return
+		}
+		repository, branch, sha = payload.Repository.FullName, payload.Branches[0].Name, payload.SHA
+		check = cistatus.Check{Name: payload.Context, State: cistatus.GitHubStatusState(payload.State), URL: payload.TargetURL}
+	}
+	s.reportCIChecks(w, r, repository, branch, sha, []cistatus.Check{check}, false)
+}
+
+// handleGitLabWebhook records GitLab pipeline events on the branch they ran
+// for. Other events are ignored.
+// POST /api/v1/webhooks/gitlab
+func (s *Server) handleGitLabWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	// GitLab sends the secret token as is rather than signing the body
+	if s.config != nil && s.config.Security.WebhookSecret != "" {
+		token := r.Header.Get("X-Gitlab-Token")
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.Security.WebhookSecret)) != 1 {
+			s.respondError(w, http.StatusUnauthorized, "Invalid webhook token")
+			return
+		}
+	}
+
+	if r.Header.Get("X-Gitlab-Event") != "Pipeline Hook" {
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+	var payload gitLabPipelinePayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+
+	pipeline := payload.ObjectAttributes
+	var checks []cistatus.Check
+	for _, job := range payload.Builds {
+		if state := cistatus.GitLabJobState(job.Status); state != "" {
+			checks = append(checks, cistatus.Check{Name: job.Name, State: state, URL: pipeline.URL})
+		}
+	}
+	if len(payload.Builds) == 0 {
+		if state := cistatus.GitLabJobState(pipeline.Status); state != "" {
+			checks = append(checks, cistatus.Check{Name: "pipeline", State: state, URL: pipeline.URL})
+		}
+	}
+	s.reportCIChecks(w, r, payload.Project.PathWithNamespace, pipeline.Ref, pipeline.SHA, checks, true)
+}
+
+// reportCIChecks hands check results to CI tracking and answers the webhook
+func (s *Server) reportCIChecks(w http.ResponseWriter, r *http.Request, repository, branch, sha string, checks []cistatus.Check, complete bool) {
+	if s.app == nil || branch == "" {
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+	status, err := s.app.ReportCIChecks(r.Context(), repository, branch, sha, checks, complete)
+	if err != nil {
+		s.respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	if status == nil {
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+	s.respondJSON(w, http.StatusOK, status)
+}
+
+// handleBeadCI returns the CI status of a bead's branches, most recently
+// updated first
+// GET /api/v1/beads/{id}/ci
+func (s *Server) handleBeadCI(w http.ResponseWriter, r *http.Request, beadID string) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	bead, err := s.app.GetBeadsManager().GetBead(beadID)
+	if err != nil {
+		s.respondError(w, http.StatusNotFound, "Bead not found")
+		return
+	}
+	ci := s.app.GetCIStatus()
+	if ci == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "CI tracking not available")
+		return
+	}
+	statuses, err := ci.ForBead(bead.ProjectID, beadID)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if statuses == nil {
+		statuses = []*cistatus.Status{}
+	}
+	s.respondJSON(w, http.StatusOK, statuses)
+}
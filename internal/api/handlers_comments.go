@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/jordanhubbard/loom/internal/comments"
 )
 
 // handleBeadComments handles comment operations for a specific bead
@@ -38,19 +40,8 @@ func (s *Server) handleBeadComments(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleGetComments retrieves all comments for a bead
-func (s *Server) handleGetComments(w http.ResponseWriter, r *http.Request, beadID string, commentsMgr interface{}) {
-	// Type assert to access GetComments method
-	type CommentsGetter interface {
-		GetComments(beadID string) (interface{}, error)
-	}
-
-	getter, ok := commentsMgr.(CommentsGetter)
-	if !ok {
-		s.respondError(w, http.StatusInternalServerError, "Invalid comments manager")
-		return
-	}
-
-	comments, err := getter.GetComments(beadID)
+func (s *Server) handleGetComments(w http.ResponseWriter, r *http.Request, beadID string, commentsMgr *comments.Manager) {
+	threads, err := commentsMgr.GetComments(beadID)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get comments: %v", err))
 		return
@@ -58,12 +49,12 @@ func (s *Server) handleGetComments(w http.ResponseWriter, r *http.Request, beadI
 
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"bead_id":  beadID,
-		"comments": comments,
+		"comments": threads,
 	})
 }
 
 // handleCreateComment creates a new comment
-func (s *Server) handleCreateComment(w http.ResponseWriter, r *http.Request, beadID string, commentsMgr interface{}) {
+func (s *Server) handleCreateComment(w http.ResponseWriter, r *http.Request, beadID string, commentsMgr *comments.Manager) {
 	// Get user from context
 	user := s.getUserFromContext(r)
 	if user == nil {
@@ -87,18 +78,7 @@ func (s *Server) handleCreateComment(w http.ResponseWriter, r *http.Request, bea
 		return
 	}
 
-	// Type assert to access CreateComment method
-	type CommentCreator interface {
-		CreateComment(beadID, authorID, authorUsername, content, parentID string) (interface{}, error)
-	}
-
-	creator, ok := commentsMgr.(CommentCreator)
-	if !ok {
-		s.respondError(w, http.StatusInternalServerError, "Invalid comments manager")
-		return
-	}
-
-	comment, err := creator.CreateComment(beadID, user.ID, user.Username, req.Content, req.ParentID)
+	comment, err := commentsMgr.CreateComment(beadID, user.ID, user.Username, req.Content, req.ParentID)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create comment: %v", err))
 		return
@@ -142,7 +122,7 @@ func (s *Server) handleComment(w http.ResponseWriter, r *http.Request) {
 }
 
 // handleUpdateComment updates a comment
-func (s *Server) handleUpdateComment(w http.ResponseWriter, r *http.Request, commentID, userID string, commentsMgr interface{}) {
+func (s *Server) handleUpdateComment(w http.ResponseWriter, r *http.Request, commentID, userID string, commentsMgr *comments.Manager) {
 	// Parse request body
 	var req struct {
 		Content string `json:"content"`
@@ -158,18 +138,7 @@ func (s *Server) handleUpdateComment(w http.ResponseWriter, r *http.Request, com
 		return
 	}
 
-	// Type assert to access UpdateComment method
-	type CommentUpdater interface {
-		UpdateComment(commentID, authorID, content string) error
-	}
-
-	updater, ok := commentsMgr.(CommentUpdater)
-	if !ok {
-		s.respondError(w, http.StatusInternalServerError, "Invalid comments manager")
-		return
-	}
-
-	if err := updater.UpdateComment(commentID, userID, req.Content); err != nil {
+	if err := commentsMgr.UpdateComment(commentID, userID, req.Content); err != nil {
 		if strings.Contains(err.Error(), "unauthorized") {
 			s.respondError(w, http.StatusForbidden, err.Error())
 			return
@@ -188,19 +157,8 @@ func (s *Server) handleUpdateComment(w http.ResponseWriter, r *http.Request, com
 }
 
 // handleDeleteComment deletes a comment
-func (s *Server) handleDeleteComment(w http.ResponseWriter, r *http.Request, commentID, userID string, commentsMgr interface{}) {
-	// Type assert to access DeleteComment method
-	type CommentDeleter interface {
-		DeleteComment(commentID, authorID string) error
-	}
-
-	deleter, ok := commentsMgr.(CommentDeleter)
-	if !ok {
-		s.respondError(w, http.StatusInternalServerError, "Invalid comments manager")
-		return
-	}
-
-	if err := deleter.DeleteComment(commentID, userID); err != nil {
+func (s *Server) handleDeleteComment(w http.ResponseWriter, r *http.Request, commentID, userID string, commentsMgr *comments.Manager) {
+	if err := commentsMgr.DeleteComment(commentID, userID); err != nil {
 		if strings.Contains(err.Error(), "unauthorized") {
 			s.respondError(w, http.StatusForbidden, err.Error())
 			return
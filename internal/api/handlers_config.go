@@ -26,7 +26,9 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
-		if err := s.app.ApplyConfigSnapshot(context.Background(), &snap); err != nil {
+		err := s.app.ApplyConfigSnapshot(context.Background(), &snap)
+		s.auditConfigChange(r, "json", err)
+		if err != nil {
 			s.respondError(w, http.StatusBadRequest, err.Error())
 			return
 		}
@@ -72,6 +74,7 @@ func (s *Server) handleConfigImportYAML(w http.ResponseWriter, r *http.Request)
 	}
 
 	snap, err := s.app.ImportConfigSnapshotYAML(context.Background(), body)
+	s.auditConfigChange(r, "yaml", err)
 	if err != nil {
 		s.respondError(w, http.StatusBadRequest, err.Error())
 		return
@@ -83,3 +86,34 @@ func (s *Server) handleConfigImportYAML(w http.ResponseWriter, r *http.Request)
 
 	s.respondJSON(w, http.StatusOK, snap)
 }
+
+// ConfigReloadStatus describes what a configuration reload applies
+type ConfigReloadStatus struct {
+	Sections []string              `json:"sections"`       // Settings that change without a restart
+	Last     *loompkg.ReloadResult `json:"last,omitempty"` // The most recent reload
+}
+
+// handleConfigReload handles GET/POST /api/v1/config/reload: describe the
+// reloadable settings, or read the config file again and apply them. The
+// same reload runs on SIGHUP.
+func (s *Server) handleConfigReload(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.respondJSON(w, http.StatusOK, ConfigReloadStatus{Sections: s.app.ReloadableSections(), Last: s.app.LastConfigReload()})
+
+	case http.MethodPost:
+		actor := "anonymous"
+		if user := s.getUserFromContext(r); user != nil {
+			actor = user.ID
+		}
+		result, err := s.app.ReloadConfig(r.Context(), actor)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, result)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
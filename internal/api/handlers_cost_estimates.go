@@ -0,0 +1,76 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/costestimate"
+)
+
+// handleGetCostEstimates handles GET /api/v1/analytics/estimates, the
+// pre-flight cost estimates of dispatches, newest first, with what the
+// finished ones actually cost
+func (s *Server) handleGetCostEstimates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	estimator := s.app.GetCostEstimator()
+	if estimator == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Cost estimates not available")
+		return
+	}
+
+	query := r.URL.Query()
+	filter := costestimate.Filter{
+		BeadID:    query.Get("bead_id"),
+		ProjectID: query.Get("project_id"),
+		Completed: query.Get("completed") == "true",
+		Limit:     100,
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+
+	list, err := estimator.List(filter)
+	if err != nil {
+		s.respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	// Callers confined to an organization see only its projects' dispatches
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		visible := list[:0]
+		for _, e := range list {
+			if s.app.ProjectOrg(e.ProjectID) == scope {
+				visible = append(visible, e)
+			}
+		}
+		list = visible
+	}
+	if list == nil {
+		list = []*costestimate.Estimate{}
+	}
+	s.respondJSON(w, http.StatusOK, list)
+}
+
+// handleGetCostEstimateAccuracy handles GET
+// /api/v1/analytics/estimates/accuracy, what finished dispatches of each
+// complexity taught the estimator and how far off its estimates were
+func (s *Server) handleGetCostEstimateAccuracy(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	estimator := s.app.GetCostEstimator()
+	if estimator == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Cost estimates not available")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, estimator.Calibrations())
+}
@@ -650,6 +650,8 @@ func TestAuthMiddleware_SkipsHealthPaths(t *testing.T) {
 		"/api/v1/auth/refresh",
 		"/",
 		"/api/openapi.yaml",
+		"/openapi.json",
+		"/api/docs",
 		"/api/v1/events/stream",
 		"/api/v1/chat/completions/stream",
 		"/api/v1/chat/completions",
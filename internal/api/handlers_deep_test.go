@@ -562,6 +562,26 @@ func TestHandlePersona_PUT_InvalidJSON(t *testing.T) {
 	}
 }
 
+func TestParsePersonaPath(t *testing.T) {
+	tests := []struct {
+		path                  string
+		name, action, version string
+	}{
+		{"default/qa-engineer", "default/qa-engineer", "", ""},
+		{"default/qa-engineer/", "default/qa-engineer", "", ""},
+		{"default/qa-engineer/versions", "default/qa-engineer", "versions", ""},
+		{"default/qa-engineer/versions/3", "default/qa-engineer", "versions", "3"},
+		{"reviewer/rollback", "reviewer", "rollback", ""},
+		{"rollback", "", "rollback", ""},
+	}
+	for _, tt := range tests {
+		name, action, version := parsePersonaPath(tt.path)
+		if name != tt.name || action != tt.action || version != tt.version {
+			t.Errorf("parsePersonaPath(%q) = %q, %q, %q; want %q, %q, %q", tt.path, name, action, version, tt.name, tt.action, tt.version)
+		}
+	}
+}
+
 func TestHandleBeads_POST_InvalidJSON(t *testing.T) {
 	s := newTestServer()
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/beads", strings.NewReader(`{invalid}`))
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/demo"
+)
+
+// demoManager returns the demo mode manager, responding with an error when
+// there is none
+func (s *Server) demoManager(w http.ResponseWriter) (*demo.Manager, bool) {
+	mgr := s.app.GetDemoManager()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Demo mode not available")
+		return nil, false
+	}
+	return mgr, true
+}
+
+// handleDemo handles GET/POST /api/v1/demo. GET lists the demo projects;
+// POST provisions a new one on a synthetic repository with seeded bugs.
+func (s *Server) handleDemo(w http.ResponseWriter, r *http.Request) {
+	mgr, ok := s.demoManager(w)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.respondJSON(w, http.StatusOK, mgr.List())
+
+	case http.MethodPost:
+		dp, err := s.app.ProvisionDemo(r.Context())
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusCreated, dp)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleDemoProject serves one demo project's pull requests
+// GET /api/v1/demo/{id}/pulls - List the project's pull requests and reviews
+func (s *Server) handleDemoProject(w http.ResponseWriter, r *http.Request) {
+	mgr, ok := s.demoManager(w)
+	if !ok {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/demo/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "pulls" {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	pulls, err := mgr.PullRequests(parts[0])
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, pulls)
+}
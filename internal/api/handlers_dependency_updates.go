@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// handleProjectDependencyUpdates checks a project for outdated dependencies
+// and files their update beads
+// POST /api/v1/projects/{id}/dependency-updates
+func (s *Server) handleProjectDependencyUpdates(w http.ResponseWriter, r *http.Request, projectID string) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req models.DependencyUpdatesRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	report, err := s.app.CheckDependencyUpdates(r.Context(), projectID, s.reviewerID(r), req.DryRun)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "project not found"):
+			s.respondError(w, http.StatusNotFound, err.Error())
+		case strings.Contains(err.Error(), "no working copy"):
+			s.respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	s.respondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// handleBeadDispatches serves a bead's dispatch history and rollback
+// GET  /api/v1/beads/{id}/dispatches                                - List recorded dispatches
+// POST /api/v1/beads/{id}/dispatches/{dispatch_id}/revert           - Roll back a dispatch
+// GET  /api/v1/beads/{id}/dispatches/{dispatch_id}/artifacts        - List a dispatch's artifacts
+// GET  /api/v1/beads/{id}/dispatches/{dispatch_id}/artifacts/verify - Re-hash a dispatch's artifacts
+func (s *Server) handleBeadDispatches(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/beads/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) < 2 || parts[1] != "dispatches" {
+		s.respondError(w, http.StatusBadRequest, "Invalid path")
+		return
+	}
+	beadID := parts[0]
+
+	if len(parts) >= 4 && parts[3] == "artifacts" {
+		s.handleDispatchArtifacts(w, r, beadID, parts[2], parts[4:])
+		return
+	}
+
+	dispatcher := s.app.GetDispatcher()
+	if dispatcher == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Dispatcher not available")
+		return
+	}
+
+	switch {
+	case len(parts) == 2:
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		snapshots, err := dispatcher.ListDispatchSnapshots(beadID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, snapshots)
+
+	case len(parts) == 4 && parts[3] == "revert":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		actor := "anonymous"
+		if user := s.getUserFromContext(r); user != nil {
+			actor = user.ID
+			if user.Username != "" {
+				actor = user.Username
+			}
+		}
+
+		result, err := dispatcher.RevertDispatch(r.Context(), beadID, parts[2], actor)
+		if err != nil {
+			switch {
+			case strings.Contains(err.Error(), "not found"):
+				s.respondError(w, http.StatusNotFound, err.Error())
+			case strings.Contains(err.Error(), "already reverted"),
+				strings.Contains(err.Error(), "still running"),
+				strings.Contains(err.Error(), "must be reverted first"):
+				s.respondError(w, http.StatusConflict, err.Error())
+			default:
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		s.respondJSON(w, http.StatusOK, result)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/evaluation"
+)
+
+// evaluationsDefaultLimit is how many evaluations are listed without ?limit
+const evaluationsDefaultLimit = 50
+
+// handleProjectJudges serves a project's judges
+// GET    /api/v1/projects/{id}/judges              - List judges
+// POST   /api/v1/projects/{id}/judges              - Create a judge
+// GET    /api/v1/projects/{id}/judges/{judge_id}   - Get a judge
+// PUT    /api/v1/projects/{id}/judges/{judge_id}   - Update a judge
+// DELETE /api/v1/projects/{id}/judges/{judge_id}   - Delete a judge
+func (s *Server) handleProjectJudges(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	mgr := s.app.GetEvaluation()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Evaluation not available")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+
+	switch len(parts) {
+	case 0:
+		switch r.Method {
+		case http.MethodGet:
+			judges, err := mgr.List(projectID)
+			if err != nil {
+				s.respondEvaluationError(w, err)
+				return
+			}
+			if judges == nil {
+				judges = []*evaluation.Judge{}
+			}
+			s.respondJSON(w, http.StatusOK, judges)
+
+		case http.MethodPost:
+			var req evaluation.JudgeRequest
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			createdBy := ""
+			if user := s.getUserFromContext(r); user != nil {
+				createdBy = user.ID
+			}
+			j, err := mgr.Create(projectID, req, createdBy)
+			if err != nil {
+				s.respondEvaluationError(w, err)
+				return
+			}
+			s.auditJudge(r, "judge.create", j)
+			s.respondJSON(w, http.StatusCreated, j)
+
+		default:
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	case 1:
+		s.handleJudge(w, r, mgr, projectID, parts[0])
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleJudge handles GET/PUT/DELETE of one judge
+func (s *Server) handleJudge(w http.ResponseWriter, r *http.Request, mgr *evaluation.Manager, projectID, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		j, err := mgr.Get(projectID, id)
+		if err != nil {
+			s.respondEvaluationError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, j)
+
+	case http.MethodPut:
+		var req evaluation.JudgeRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		j, err := mgr.Update(projectID, id, req)
+		if err != nil {
+			s.respondEvaluationError(w, err)
+			return
+		}
+		s.auditJudge(r, "judge.update", j)
+		s.respondJSON(w, http.StatusOK, j)
+
+	case http.MethodDelete:
+		j, err := mgr.Get(projectID, id)
+		if err != nil {
+			s.respondEvaluationError(w, err)
+			return
+		}
+		if err := mgr.Delete(projectID, id); err != nil {
+			s.respondEvaluationError(w, err)
+			return
+		}
+		s.auditJudge(r, "judge.delete", j)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleProjectEvaluations lists the grades a project's dispatches got
+// GET /api/v1/projects/{id}/evaluations?bead_id=&provider_id=&limit=50
+func (s *Server) handleProjectEvaluations(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	mgr := s.app.GetEvaluation()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Evaluation not available")
+		return
+	}
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) != 0 {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	q := r.URL.Query()
+	filter := evaluation.Filter{
+		ProjectID:  projectID,
+		BeadID:     q.Get("bead_id"),
+		ProviderID: q.Get("provider_id"),
+		Limit:      evaluationsDefaultLimit,
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = n
+	}
+	list, err := mgr.Evaluations(filter)
+	if err != nil {
+		s.respondEvaluationError(w, err)
+		return
+	}
+	if list == nil {
+		list = []*evaluation.Evaluation{}
+	}
+	s.respondJSON(w, http.StatusOK, list)
+}
+
+// handleGetProviderQuality returns the mean judge grade of each provider's
+// work within the evaluation window
+// GET /api/v1/analytics/quality
+func (s *Server) handleGetProviderQuality(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	mgr := s.app.GetEvaluation()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Evaluation not available")
+		return
+	}
+	quality, err := mgr.Quality()
+	if err != nil {
+		s.respondEvaluationError(w, err)
+		return
+	}
+	if quality == nil {
+		quality = []evaluation.ProviderQuality{}
+	}
+	s.respondJSON(w, http.StatusOK, quality)
+}
+
+// respondEvaluationError maps evaluation errors to status codes
+func (s *Server) respondEvaluationError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.HasPrefix(msg, "invalid"):
+		s.respondError(w, http.StatusBadRequest, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+)
+
+// webhookDeliveryListOptions pages a subscription's delivery log, newest first
+var webhookDeliveryListOptions = listOptions{defaultLimit: 50, sorts: []listSort{{"created_at", "ts"}}, defaultDesc: true}
+
+// WebhookDeliveryPage is the body of a delivery log page
+type WebhookDeliveryPage struct {
+	Deliveries []eventhooks.Delivery `json:"deliveries"`
+	Count      int                   `json:"count"`
+	Total      int                   `json:"total"`
+	Limit      int                   `json:"limit"`
+	Offset     int                   `json:"offset"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+// eventWebhooks returns the event webhook manager, responding with an error
+// when there is none
+func (s *Server) eventWebhooks(w http.ResponseWriter) (*eventhooks.Manager, bool) {
+	hooks := s.app.GetEventWebhooks()
+	if hooks == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Event webhooks not available")
+		return nil, false
+	}
+	return hooks, true
+}
+
+// handleEventWebhooks handles GET/POST /api/v1/event-webhooks
+func (s *Server) handleEventWebhooks(w http.ResponseWriter, r *http.Request) {
+	hooks, ok := s.eventWebhooks(w)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := hooks.List()
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		var req eventhooks.SubscriptionRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		createdBy := ""
+		if user := s.getUserFromContext(r); user != nil {
+			createdBy = user.ID
+		}
+		sub, err := hooks.Create(req, createdBy)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditEventWebhook(r, "event_webhook.create", sub)
+		s.respondJSON(w, http.StatusCreated, sub)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleEventWebhook serves one subscription and its delivery log
+// GET    /api/v1/event-webhooks/{id}                                    - Get a subscription
+// PUT    /api/v1/event-webhooks/{id}                                    - Update a subscription
+// DELETE /api/v1/event-webhooks/{id}                                    - Delete a subscription and its log
+// POST   /api/v1/event-webhooks/{id}/test                               - Send a ping event
+// GET    /api/v1/event-webhooks/{id}/deliveries                         - Page through the delivery log
+// POST   /api/v1/event-webhooks/{id}/deliveries/{delivery_id}/redeliver - Send a delivery again
+func (s *Server) handleEventWebhook(w http.ResponseWriter, r *http.Request) {
+	hooks, ok := s.eventWebhooks(w)
+	if !ok {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/event-webhooks/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	id := parts[0]
+	if id == "" {
+		s.respondError(w, http.StatusBadRequest, "Webhook ID required")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.handleEventWebhookResource(w, r, hooks, id)
+
+	case len(parts) == 2 && parts[1] == "test":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		d, err := hooks.Test(id)
+		if err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, d)
+
+	case len(parts) == 2 && parts[1] == "deliveries":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		lq, err := parseListQuery(r, webhookDeliveryListOptions)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		deliveries, total, err := hooks.Deliveries(eventhooks.DeliveryFilter{
+			SubscriptionID: id,
+			Status:         r.URL.Query().Get("status"),
+			Ascending:      !lq.desc,
+			Limit:          lq.fetch(),
+			Offset:         lq.offset,
+			After:          lq.after,
+		})
+		if err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		page, p := pageOf(deliveries, lq, total, func(d *eventhooks.Delivery) []interface{} { return []interface{}{d.CreatedAt, d.ID} })
+		s.respondList(w, r, "deliveries", page, p, nil)
+
+	case len(parts) == 4 && parts[1] == "deliveries" && parts[3] == "redeliver":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		d, err := hooks.Redeliver(id, parts[2])
+		if err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, d)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleEventWebhookResource handles GET/PUT/DELETE of one subscription
+func (s *Server) handleEventWebhookResource(w http.ResponseWriter, r *http.Request, hooks *eventhooks.Manager, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		sub, err := hooks.Get(id)
+		if err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, sub)
+
+	case http.MethodPut:
+		var req eventhooks.SubscriptionRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		sub, err := hooks.Update(id, req)
+		if err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		s.auditEventWebhook(r, "event_webhook.update", sub)
+		s.respondJSON(w, http.StatusOK, sub)
+
+	case http.MethodDelete:
+		sub, err := hooks.Get(id)
+		if err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		if err := hooks.Delete(id); err != nil {
+			s.respondEventWebhookError(w, err)
+			return
+		}
+		s.auditEventWebhook(r, "event_webhook.delete", sub)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// respondEventWebhookError maps event webhook errors to status codes
+func (s *Server) respondEventWebhookError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.Contains(msg, "must"), strings.Contains(msg, "invalid"):
+		s.respondError(w, http.StatusBadRequest, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
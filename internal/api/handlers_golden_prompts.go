@@ -0,0 +1,207 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/reports"
+)
+
+// goldenRunsDefaultLimit is how many runs are listed without ?limit
+const goldenRunsDefaultLimit = 20
+
+// handleProjectGoldenPrompts serves a project's golden prompt suite
+// GET    /api/v1/projects/{id}/golden-prompts                        - List cases
+// POST   /api/v1/projects/{id}/golden-prompts                        - Create a case
+// GET    /api/v1/projects/{id}/golden-prompts/{case_id}              - Get a case
+// PUT    /api/v1/projects/{id}/golden-prompts/{case_id}              - Update a case
+// DELETE /api/v1/projects/{id}/golden-prompts/{case_id}              - Delete a case
+// POST   /api/v1/projects/{id}/golden-prompts/run                    - Run the suite now
+// GET    /api/v1/projects/{id}/golden-prompts/runs                   - Recent runs, newest first
+// GET    /api/v1/projects/{id}/golden-prompts/runs/{run_id}          - One run
+// GET    /api/v1/projects/{id}/golden-prompts/runs/{run_id}/report   - A run as a pdf, html or csv report
+func (s *Server) handleProjectGoldenPrompts(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	mgr := s.app.GetGoldenPrompts()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Golden prompts not available")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+
+	switch {
+	case len(parts) == 0:
+		switch r.Method {
+		case http.MethodGet:
+			cases, err := mgr.List(projectID)
+			if err != nil {
+				s.respondGoldenPromptError(w, err)
+				return
+			}
+			s.respondJSON(w, http.StatusOK, cases)
+
+		case http.MethodPost:
+			var req goldenprompts.CaseRequest
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			createdBy := ""
+			if user := s.getUserFromContext(r); user != nil {
+				createdBy = user.ID
+			}
+			c, err := mgr.Create(projectID, req, createdBy)
+			if err != nil {
+				s.respondGoldenPromptError(w, err)
+				return
+			}
+			s.auditGoldenPrompt(r, "golden_prompt.create", c)
+			s.respondJSON(w, http.StatusCreated, c)
+
+		default:
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	case parts[0] == "run" && len(parts) == 1:
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		run, err := mgr.Run(r.Context(), projectID, goldenprompts.TriggerManual, "")
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, run)
+
+	case parts[0] == "runs":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		s.handleGoldenPromptRuns(w, r, mgr, projectID, parts[1:])
+
+	case len(parts) == 1:
+		s.handleGoldenPromptCase(w, r, mgr, projectID, parts[0])
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleGoldenPromptCase handles GET/PUT/DELETE of one case
+func (s *Server) handleGoldenPromptCase(w http.ResponseWriter, r *http.Request, mgr *goldenprompts.Manager, projectID, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		c, err := mgr.Get(projectID, id)
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, c)
+
+	case http.MethodPut:
+		var req goldenprompts.CaseRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		c, err := mgr.Update(projectID, id, req)
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		s.auditGoldenPrompt(r, "golden_prompt.update", c)
+		s.respondJSON(w, http.StatusOK, c)
+
+	case http.MethodDelete:
+		c, err := mgr.Get(projectID, id)
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		if err := mgr.Delete(projectID, id); err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		s.auditGoldenPrompt(r, "golden_prompt.delete", c)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleGoldenPromptRuns lists runs, returns one, or renders one as a report
+func (s *Server) handleGoldenPromptRuns(w http.ResponseWriter, r *http.Request, mgr *goldenprompts.Manager, projectID string, parts []string) {
+	switch {
+	case len(parts) == 0:
+		limit := goldenRunsDefaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			limit = n
+		}
+		runs, err := mgr.Runs(projectID, limit)
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, runs)
+
+	case len(parts) == 1:
+		run, err := mgr.GetRun(projectID, parts[0])
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, run)
+
+	case len(parts) == 2 && parts[1] == "report":
+		run, err := mgr.GetRun(projectID, parts[0])
+		if err != nil {
+			s.respondGoldenPromptError(w, err)
+			return
+		}
+		format := strings.ToLower(r.URL.Query().Get("format"))
+		if format == "" {
+			format = reports.FormatPDF
+		}
+		out, err := reports.Render(goldenprompts.RunReport(run), format, nil)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.Header().Set("Content-Type", out.ContentType)
+		w.Header().Set("Content-Disposition", "attachment; filename=\""+out.Filename+"\"")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write(out.Data)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// respondGoldenPromptError maps golden prompt errors to status codes
+func (s *Server) respondGoldenPromptError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.Contains(msg, "already running"):
+		s.respondError(w, http.StatusConflict, msg)
+	case strings.HasPrefix(msg, "invalid"):
+		s.respondError(w, http.StatusBadRequest, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
@@ -0,0 +1,191 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/issuesync"
+)
+
+// jiraWebhookPayload is the part of a Jira issue or comment event the
+// issue sync reads
+type jiraWebhookPayload struct {
+	WebhookEvent string               `json:"webhookEvent"`
+	Issue        *issuesync.JiraIssue `json:"issue,omitempty"`
+	Comment      *struct {
+		Body   string `json:"body"`
+		Author struct {
+			DisplayName string `json:"displayName"`
+		} `json:"author"`
+	} `json:"comment,omitempty"`
+}
+
+// handleProjectIssueSync lists the issues a project imported, or syncs it
+// with its tracker now
+// GET  /api/v1/projects/{id}/issue-sync
+// POST /api/v1/projects/{id}/issue-sync
+func (s *Server) handleProjectIssueSync(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) > 0 {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	mgr := s.app.GetIssueSync()
+	if !mgr.Enabled() {
+		s.respondError(w, http.StatusServiceUnavailable, "Issue sync not enabled")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+	if mgr.Project(projectID) == nil {
+		s.respondError(w, http.StatusNotFound, "Project does not sync with an issue tracker")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		links, err := mgr.Links(projectID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if links == nil {
+			links = []*issuesync.Link{}
+		}
+		s.respondJSON(w, http.StatusOK, links)
+		return
+	}
+
+	res, err := mgr.Sync(r.Context(), projectID)
+	if err != nil {
+		s.respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, res)
+}
+
+// syncGitHubIssue applies an issues or issue_comment event to the bead of
+// the issue. Failures are logged; the event still reaches the motivation
+// system.
+func (s *Server) syncGitHubIssue(r *http.Request, eventType string, payload *GitHubWebhookPayload) {
+	if s.app == nil || payload.Issue == nil || payload.Repository == nil {
+		return
+	}
+	repo := payload.Repository.FullName
+	key := fmt.Sprint(payload.Issue.Number)
+	var err error
+	switch {
+	case eventType == "issues":
+		_, _, err = s.app.SyncIssue(r.Context(), issuesync.TrackerGitHub, repo, gitHubIssue(payload.Issue))
+	case eventType == "issue_comment" && payload.Action == "created" && payload.Comment != nil:
+		author := ""
+		if payload.Comment.User != nil {
+			author = payload.Comment.User.Login
+		}
+		err = s.app.IssueCommented(issuesync.TrackerGitHub, repo, key, author, payload.Comment.Body)
+	}
+	if err != nil {
+		log.Printf("[IssueSync] Failed to sync %s#%s: %v", repo, key, err)
+	}
+}
+
+// gitHubIssue converts the issue of a GitHub webhook
+func gitHubIssue(i *GitHubIssue) issuesync.Issue {
+	issue := issuesync.Issue{
+		Key:   fmt.Sprint(i.Number),
+		Title: i.Title,
+		Body:  i.Body,
+		State: issuesync.GitHubState(i.State),
+		URL:   i.URL,
+	}
+	for _, l := range i.Labels {
+		issue.Labels = append(issue.Labels, l.Name)
+	}
+	if t, err := time.Parse(time.RFC3339, i.UpdatedAt); err == nil {
+		issue.UpdatedAt = t
+	}
+	return issue
+}
+
+// handleJiraWebhook applies Jira issue and comment events to the beads of
+// the issues
+// POST /api/v1/webhooks/jira
+func (s *Server) handleJiraWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, "Failed to read request body")
+		return
+	}
+	defer r.Body.Close()
+
+	// Jira signs the body like GitHub when the webhook has a secret
+	if s.config != nil && s.config.Security.WebhookSecret != "" {
+		if !verifyGitHubSignature(body, r.Header.Get("X-Hub-Signature"), s.config.Security.WebhookSecret) {
+			s.respondError(w, http.StatusUnauthorized, "Invalid webhook signature")
+			return
+		}
+	}
+
+	var payload jiraWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid JSON payload")
+		return
+	}
+	if s.app == nil || payload.Issue == nil {
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+		return
+	}
+
+	issue := payload.Issue.Issue(s.jiraBaseURL())
+	projectKey := payload.Issue.Fields.Project.Key
+	if projectKey == "" {
+		projectKey, _, _ = strings.Cut(issue.Key, "-")
+	}
+	switch payload.WebhookEvent {
+	case "jira:issue_created", "jira:issue_updated":
+		_, outcome, err := s.app.SyncIssue(r.Context(), issuesync.TrackerJira, projectKey, issue)
+		if err != nil {
+			s.respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": outcome})
+	case "comment_created":
+		if payload.Comment == nil {
+			s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+			return
+		}
+		if err := s.app.IssueCommented(issuesync.TrackerJira, projectKey, issue.Key, payload.Comment.Author.DisplayName, payload.Comment.Body); err != nil {
+			s.respondError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": "recorded"})
+	default:
+		s.respondJSON(w, http.StatusOK, map[string]string{"status": "ignored"})
+	}
+}
+
+// jiraBaseURL is the Jira site issue links point to
+func (s *Server) jiraBaseURL() string {
+	if s.config == nil {
+		return ""
+	}
+	return s.config.IssueSync.Jira.URL
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/jordanhubbard/loom/internal/knowledge"
+	"github.com/jordanhubbard/loom/internal/loom"
+)
+
+// handleProjectKnowledge serves a project's knowledge graph
+// GET  /api/v1/projects/{id}/knowledge/lessons?path=pkg/provider
+// GET  /api/v1/projects/{id}/knowledge/graph?kind=file&node=pkg/provider/client.go&depth=2
+// POST /api/v1/projects/{id}/knowledge/index
+func (s *Server) handleProjectKnowledge(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if len(parts) != 1 {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	graph := s.app.GetKnowledgeGraph()
+	if graph == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Knowledge graph not available")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	query := r.URL.Query()
+	switch parts[0] {
+	case "lessons":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		paths := query["path"]
+		if len(paths) == 0 {
+			s.respondError(w, http.StatusBadRequest, "path is required")
+			return
+		}
+		lessons, err := s.app.LessonsTouching(projectID, paths)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if lessons == nil {
+			lessons = []*loom.KnowledgeLesson{}
+		}
+		s.respondJSON(w, http.StatusOK, lessons)
+
+	case "graph":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		node := knowledge.Node{Kind: query.Get("kind"), ID: query.Get("node")}
+		switch node.Kind {
+		case knowledge.KindLesson, knowledge.KindBead, knowledge.KindAgent, knowledge.KindCommit, knowledge.KindFile:
+		default:
+			s.respondError(w, http.StatusBadRequest, "kind must be lesson, bead, agent, commit or file")
+			return
+		}
+		if node.ID == "" {
+			s.respondError(w, http.StatusBadRequest, "node is required")
+			return
+		}
+		depth := 1
+		if v := query.Get("depth"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				s.respondError(w, http.StatusBadRequest, "depth must be a positive integer")
+				return
+			}
+			depth = n
+		}
+		sub, err := graph.Walk(projectID, node, depth)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, sub)
+
+	case "index":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		report, err := graph.IndexProject(r.Context(), projectID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, report)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
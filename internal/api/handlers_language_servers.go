@@ -0,0 +1,37 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// handleProjectLanguageServers lists or stops a project's language servers
+// GET    /api/v1/projects/{id}/language-servers
+// DELETE /api/v1/projects/{id}/language-servers
+func (s *Server) handleProjectLanguageServers(w http.ResponseWriter, r *http.Request, projectID string) {
+	switch r.Method {
+	case http.MethodGet:
+		servers, err := s.app.LanguageServers(projectID)
+		if err != nil {
+			s.respondLanguageServersError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, servers)
+	case http.MethodDelete:
+		if err := s.app.StopLanguageServers(projectID, s.reviewerID(r)); err != nil {
+			s.respondLanguageServersError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+func (s *Server) respondLanguageServersError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "project not found") {
+		s.respondError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	s.respondError(w, http.StatusInternalServerError, err.Error())
+}
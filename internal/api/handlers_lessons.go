@@ -0,0 +1,197 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// supersededLessonsDefaultLimit is how many lessons are listed without ?limit
+const supersededLessonsDefaultLimit = 50
+
+// handleProjectLessons serves reports on a project's lessons
+// GET  /api/v1/projects/{id}/lessons/superseded             - Most recently superseded first
+// POST /api/v1/projects/{id}/lessons/{lesson_id}/reinstate  - Put a superseded lesson back into prompts
+// POST /api/v1/projects/{id}/lessons/{lesson_id}/promote    - Propose a lesson for the organization's shared tier
+// GET  /api/v1/projects/{id}/lessons/effectiveness          - Most and least helpful lessons by dispatch outcome
+func (s *Server) handleProjectLessons(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	switch {
+	case len(parts) == 1 && (parts[0] == "superseded" || parts[0] == "effectiveness"):
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+	case len(parts) == 2 && (parts[1] == "reinstate" || parts[1] == "promote"):
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	if len(parts) == 2 && parts[1] == "promote" {
+		var req models.PromoteLessonRequest
+		if r.ContentLength != 0 {
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+		lesson, err := s.app.PromoteLesson(projectID, parts[0], req.Title, req.Detail)
+		if err != nil {
+			s.respondError(w, lessonErrorStatus(err), err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusCreated, lesson)
+		return
+	}
+	if len(parts) == 2 {
+		lesson, err := s.app.ReinstateLesson(projectID, parts[0])
+		if err != nil {
+			s.respondError(w, lessonErrorStatus(err), err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, lesson)
+		return
+	}
+
+	if parts[0] == "effectiveness" {
+		limit, ok := s.positiveQueryInt(w, r, "limit", 0)
+		if !ok {
+			return
+		}
+		minInjections, ok := s.positiveQueryInt(w, r, "min_injections", 0)
+		if !ok {
+			return
+		}
+		report, err := s.app.LessonEffectiveness(projectID, minInjections, limit)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, report)
+		return
+	}
+
+	limit, ok := s.positiveQueryInt(w, r, "limit", supersededLessonsDefaultLimit)
+	if !ok {
+		return
+	}
+	lessons, err := s.app.ListSupersededLessons(projectID, limit)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if lessons == nil {
+		lessons = []*models.Lesson{}
+	}
+	s.respondJSON(w, http.StatusOK, lessons)
+}
+
+// handleOrgLessons serves an organization's shared lessons
+// GET    /api/v1/orgs/{id}/lessons                      - List shared lessons, newest first
+// POST   /api/v1/orgs/{id}/lessons                      - Add an approved shared lesson
+// POST   /api/v1/orgs/{id}/lessons/{lesson_id}/approve  - Approve a promoted lesson
+// DELETE /api/v1/orgs/{id}/lessons/{lesson_id}          - Reject or retire a shared lesson
+func (s *Server) handleOrgLessons(w http.ResponseWriter, r *http.Request, orgID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	switch {
+	case len(parts) == 0 && r.Method == http.MethodGet:
+		status := models.ShareStatus(r.URL.Query().Get("status"))
+		if status != "" && status != models.ShareStatusProposed && status != models.ShareStatusShared {
+			s.respondError(w, http.StatusBadRequest, "status must be proposed or shared")
+			return
+		}
+		limit, ok := s.positiveQueryInt(w, r, "limit", supersededLessonsDefaultLimit)
+		if !ok {
+			return
+		}
+		lessons, err := s.app.ListSharedLessons(orgID, status, limit)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if lessons == nil {
+			lessons = []*models.Lesson{}
+		}
+		s.respondJSON(w, http.StatusOK, lessons)
+
+	case len(parts) == 0 && r.Method == http.MethodPost:
+		var req models.SharedLessonRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.Title == "" || req.Detail == "" {
+			s.respondError(w, http.StatusBadRequest, "title and detail are required")
+			return
+		}
+		lesson, err := s.app.CreateSharedLesson(orgID, req.Category, req.Title, req.Detail)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusCreated, lesson)
+
+	case len(parts) == 2 && parts[1] == "approve" && r.Method == http.MethodPost:
+		lesson, err := s.app.ApproveSharedLesson(orgID, parts[0])
+		if err != nil {
+			s.respondError(w, lessonErrorStatus(err), err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, lesson)
+
+	case len(parts) == 1 && r.Method == http.MethodDelete:
+		if err := s.app.DeleteSharedLesson(orgID, parts[0]); err != nil {
+			s.respondError(w, lessonErrorStatus(err), err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case len(parts) <= 1 || (len(parts) == 2 && parts[1] == "approve"):
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// lessonErrorStatus is the HTTP status of a failed lesson operation
+func lessonErrorStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		return http.StatusNotFound
+	case strings.Contains(err.Error(), "already promoted"):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+// positiveQueryInt returns a query parameter that must be a positive
+// integer, or def when it is absent. It responds with 400 and reports
+// false when the parameter is invalid.
+func (s *Server) positiveQueryInt(w http.ResponseWriter, r *http.Request, name string, def int) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		s.respondError(w, http.StatusBadRequest, name+" must be a positive integer")
+		return 0, false
+	}
+	return n, true
+}
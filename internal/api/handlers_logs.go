@@ -4,13 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jordanhubbard/loom/internal/logging"
 )
 
+// logListOptions pages log entries, newest first
+var logListOptions = listOptions{defaultLimit: 100, sorts: []listSort{{"timestamp", "ts"}}, defaultDesc: true}
+
 // HandleLogsRecent returns recent log entries
 func (s *Server) HandleLogsRecent(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -19,12 +21,10 @@ func (s *Server) HandleLogsRecent(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Parse query parameters
-	limitStr := r.URL.Query().Get("limit")
-	limit := 100
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
-			limit = l
-		}
+	lq, err := parseListQuery(r, logListOptions)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	level := r.URL.Query().Get("level")
@@ -35,10 +35,6 @@ func (s *Server) HandleLogsRecent(w http.ResponseWriter, r *http.Request) {
 	beadID := r.URL.Query().Get("bead_id")
 	projectID := r.URL.Query().Get("project_id")
 
-	var logs []logging.LogEntry
-	var err error
-
-	// If 'since' is provided, query from database
 	var since time.Time
 	var until time.Time
 	if sinceStr != "" {
@@ -58,18 +54,22 @@ func (s *Server) HandleLogsRecent(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	logs, err = s.logManager.Query(limit, level, source, agentID, beadID, projectID, since, until)
+	filter := logging.Filter{
+		Level: level, Source: source, AgentID: agentID, BeadID: beadID, ProjectID: projectID,
+		Since: since, Until: until,
+	}
+	var after *logging.PageKey
+	if lq.after != nil {
+		after = &logging.PageKey{Timestamp: lq.after[0].(time.Time), ID: lq.after[1].(string)}
+	}
+	logs, total, err := s.logManager.QueryPage(filter, after, lq.fetch(), lq.offset, !lq.desc)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to query logs: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	response := map[string]interface{}{
-		"logs":  logs,
-		"count": len(logs),
-	}
-
-	s.respondJSON(w, http.StatusOK, response)
+	page, p := pageOf(logs, lq, total, func(e logging.LogEntry) []interface{} { return []interface{}{e.Timestamp, e.ID} })
+	s.respondList(w, r, "logs", page, p, nil)
 }
 
 // HandleLogsStream streams log entries via Server-Sent Events (SSE)
@@ -267,3 +267,56 @@ func getLogMeta(meta map[string]interface{}, key string) string {
 	}
 	return ""
 }
+
+// LogLevelsRequest changes log levels at runtime. An empty module level
+// removes that module's override.
+type LogLevelsRequest struct {
+	Default string            `json:"default,omitempty"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
+
+// handleLogLevels handles GET/PUT /api/v1/logs/levels, the default log level
+// and per-module overrides. Changes last until the next restart.
+func (s *Server) handleLogLevels(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.respondJSON(w, http.StatusOK, logging.Levels())
+
+	case http.MethodPut:
+		var req LogLevelsRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		// Check every level before applying any
+		if req.Default != "" {
+			if _, err := logging.ParseLevel(req.Default); err != nil {
+				s.respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		for module, level := range req.Modules {
+			if level == "" {
+				continue
+			}
+			if _, err := logging.ParseLevel(level); err != nil {
+				s.respondError(w, http.StatusBadRequest, fmt.Sprintf("module %s: %v", module, err))
+				return
+			}
+		}
+		if req.Default != "" {
+			_ = logging.SetDefaultLevel(req.Default)
+		}
+		for module, level := range req.Modules {
+			if err := logging.SetModuleLevel(module, level); err != nil {
+				s.respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		s.auditLogLevels(r, req)
+		s.respondJSON(w, http.StatusOK, logging.Levels())
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
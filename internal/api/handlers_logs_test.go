@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/logging"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+func TestHandleLogLevels(t *testing.T) {
+	s := &Server{config: &config.Config{}}
+	t.Cleanup(func() {
+		_ = logging.SetDefaultLevel("info")
+		_ = logging.SetModuleLevel("dispatch", "")
+	})
+
+	w := httptest.NewRecorder()
+	s.handleLogLevels(w, httptest.NewRequest(http.MethodPut, "/api/v1/logs/levels",
+		strings.NewReader(`{"modules":{"dispatch":"debug"}}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d: %s", w.Code, w.Body.String())
+	}
+	var got logging.LevelSettings
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Modules["dispatch"] != "debug" {
+		t.Errorf("dispatch level = %q, want debug", got.Modules["dispatch"])
+	}
+
+	// An invalid level rejects the whole change
+	w = httptest.NewRecorder()
+	s.handleLogLevels(w, httptest.NewRequest(http.MethodPut, "/api/v1/logs/levels",
+		strings.NewReader(`{"default":"warn","modules":{"git":"chatty"}}`)))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("invalid level status = %d, want 400", w.Code)
+	}
+	if logging.Levels().Default != "info" {
+		t.Errorf("default level changed by a rejected request")
+	}
+
+	w = httptest.NewRecorder()
+	s.handleLogLevels(w, httptest.NewRequest(http.MethodDelete, "/api/v1/logs/levels", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("DELETE status = %d, want 405", w.Code)
+	}
+}
+
+func TestLoggingMiddleware_RequestID(t *testing.T) {
+	s := &Server{config: &config.Config{}}
+	var fields string
+	h := s.loggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, a := range logging.Fields(r.Context()) {
+			fields += a.Key + "=" + a.Value.String() + " "
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/beads", nil)
+	req.Header.Set("X-Request-ID", "req-42")
+	h.ServeHTTP(w, req)
+	if w.Header().Get("X-Request-ID") != "req-42" || !strings.Contains(fields, "request_id=req-42") {
+		t.Errorf("request ID not propagated: header %q, fields %q", w.Header().Get("X-Request-ID"), fields)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/beads", nil))
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Error("expected a generated request ID")
+	}
+}
@@ -589,7 +589,7 @@ func TestHandleRecommendedModels_MethodNotAllowed(t *testing.T) {
 
 func TestHandlePersonas_MethodNotAllowed(t *testing.T) {
 	s := newTestServer()
-	req := httptest.NewRequest(http.MethodPost, "/api/v1/personas", nil)
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/personas", nil)
 	w := httptest.NewRecorder()
 	s.handlePersonas(w, req)
 	if w.Code != http.StatusMethodNotAllowed {
@@ -599,7 +599,7 @@ func TestHandlePersonas_MethodNotAllowed(t *testing.T) {
 
 func TestHandlePersona_MethodNotAllowed(t *testing.T) {
 	s := newTestServer()
-	req := httptest.NewRequest(http.MethodDelete, "/api/v1/personas/test", nil)
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/personas/test", nil)
 	w := httptest.NewRecorder()
 	s.handlePersona(w, req)
 	if w.Code != http.StatusMethodNotAllowed {
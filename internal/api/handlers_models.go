@@ -1,6 +1,14 @@
 package api
 
-import "net/http"
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/loom"
+	"github.com/jordanhubbard/loom/internal/pricing"
+)
 
 // handleRecommendedModels handles GET /api/v1/models/recommended
 func (s *Server) handleRecommendedModels(w http.ResponseWriter, r *http.Request) {
@@ -12,3 +20,183 @@ func (s *Server) handleRecommendedModels(w http.ResponseWriter, r *http.Request)
 	models := s.app.ListModelCatalog()
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{"models": models})
 }
+
+// handleModelDeprecations handles GET /api/v1/models/deprecations
+func (s *Server) handleModelDeprecations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	notices, err := s.app.ListModelDeprecations()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"providers": notices,
+		"catalog":   s.app.GetModelCatalog().Deprecations(),
+	})
+}
+
+// handleModelMetadata handles GET /api/v1/models/metadata. With ?model=
+// it returns the merged metadata for one model.
+func (s *Server) handleModelMetadata(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	if model := r.URL.Query().Get("model"); model != "" {
+		md, ok := s.app.GetModelCatalog().ModelMetadata(model)
+		if !ok {
+			s.respondError(w, http.StatusNotFound, "No metadata for model "+model)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, md)
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"models": s.app.ListModelMetadata(),
+	})
+}
+
+// handleModelMigrations handles POST /api/v1/models/migrations, which
+// replays recent prompts against a replacement model and reports deltas
+func (s *Server) handleModelMigrations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var req loom.ModelMigrationRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.ProviderID == "" {
+		s.respondError(w, http.StatusBadRequest, "provider_id is required")
+		return
+	}
+
+	report, err := s.app.RunModelMigration(r.Context(), req)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, report)
+}
+
+// handleModelPricing handles /api/v1/models/pricing. GET lists the pricing
+// catalog, or with ?model= (and optionally ?provider_id=) returns the price
+// a request to that model is costed at. PUT sets a price, which refreshes
+// leave alone; DELETE removes a price set that way, reverting the model to
+// the dataset's price.
+func (s *Server) handleModelPricing(w http.ResponseWriter, r *http.Request) {
+	prices := s.app.GetPricing()
+	if prices == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Pricing not available")
+		return
+	}
+	query := r.URL.Query()
+
+	switch r.Method {
+	case http.MethodGet:
+		if model := query.Get("model"); model != "" {
+			p, ok := prices.Lookup(query.Get("provider_id"), model)
+			if !ok {
+				s.respondError(w, http.StatusNotFound, "No price for model "+model)
+				return
+			}
+			s.respondJSON(w, http.StatusOK, p)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{"prices": prices.List()})
+	case http.MethodPut:
+		var req pricing.Price
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		p, err := prices.Set(&req)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, p)
+	case http.MethodDelete:
+		model := query.Get("model")
+		if model == "" {
+			s.respondError(w, http.StatusBadRequest, "model is required")
+			return
+		}
+		if err := prices.Delete(query.Get("provider_id"), model); err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				s.respondError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleRefreshModelPricing handles POST /api/v1/models/pricing/refresh,
+// which reloads the dataset and configured prices now
+func (s *Server) handleRefreshModelPricing(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	prices := s.app.GetPricing()
+	if prices == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Pricing not available")
+		return
+	}
+	report, err := prices.Refresh(r.Context())
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, report)
+}
+
+// handleEmbeddingStatus handles GET /api/v1/models/embeddings, which
+// reports the embedding version lessons are searched with and how many are
+// still to be re-embedded with it
+func (s *Server) handleEmbeddingStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	reembedder := s.app.GetReembedder()
+	if reembedder == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Lesson embeddings not available")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, reembedder.Status())
+}
+
+// handleReembedLessons handles POST /api/v1/models/embeddings/reembed,
+// which starts re-embedding lessons now instead of at the next scheduled
+// run. It runs in the background at the configured rate.
+func (s *Server) handleReembedLessons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	reembedder := s.app.GetReembedder()
+	if reembedder == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Lesson embeddings not available")
+		return
+	}
+	go func() {
+		if _, err := reembedder.RunOnce(context.Background()); err != nil {
+			log.Printf("[API] Re-embedding lessons failed: %v", err)
+		}
+	}()
+	s.respondJSON(w, http.StatusAccepted, reembedder.Status())
+}
@@ -8,11 +8,17 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/database"
 	"github.com/jordanhubbard/loom/internal/notifications"
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
+// notificationListOptions pages a user's notifications, newest first
+var notificationListOptions = listOptions{defaultLimit: 50, sorts: []listSort{{"created_at", "ts"}, {"priority", "its"}}, defaultDesc: true}
+
 // handleGetNotifications handles GET requests for user notifications
-// GET /api/v1/notifications?status=unread&limit=50
+// GET /api/v1/notifications?status=unread&priority=high&project_id=p&limit=50&cursor=xxx&sort=-priority
 func (s *Server) handleGetNotifications(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -33,49 +39,40 @@ func (s *Server) handleGetNotifications(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Parse query parameters
-	status := r.URL.Query().Get("status")
-	priority := r.URL.Query().Get("priority")
-	limit := 50
-	offset := 0
-
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
-			limit = parsed
-		}
+	lq, err := parseListQuery(r, notificationListOptions)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
 	}
-
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-			offset = parsed
-		}
+	filters := database.NotificationFilters{
+		Status:    r.URL.Query().Get("status"),
+		Priority:  r.URL.Query().Get("priority"),
+		ProjectID: r.URL.Query().Get("project_id"),
+		SortBy:    lq.sort,
+		Ascending: !lq.desc,
+		Limit:     lq.fetch(),
+		Offset:    lq.offset,
+		After:     lq.after,
 	}
 
-	notifs, err := notificationMgr.GetNotifications(user.ID, status, limit, offset)
+	notifs, total, err := notificationMgr.QueryNotifications(user.ID, filters)
 	if err != nil {
 		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get notifications: %v", err))
 		return
 	}
 
-	// Filter by priority if specified
-	if priority != "" {
-		filtered := make([]*notifications.Notification, 0)
-		for _, n := range notifs {
-			if n.Priority == priority {
-				filtered = append(filtered, n)
-			}
+	page, p := pageOf(notifs, lq, total, func(n *notifications.Notification) []interface{} {
+		if lq.sort == "priority" {
+			return []interface{}{database.NotificationPriorityRank(n.Priority), n.CreatedAt, n.ID}
 		}
-		notifs = filtered
-	}
-
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"notifications": notifs,
-		"count":         len(notifs),
-		"limit":         limit,
-		"offset":        offset,
+		return []interface{}{n.CreatedAt, n.ID}
 	})
+	s.respondList(w, r, "notifications", page, p, nil)
 }
 
-// handleNotificationStream handles SSE endpoint for real-time user notifications
+// handleNotificationStream handles SSE endpoint for real-time user
+// notifications. Changes to the user's unread counts are sent as
+// unread_counts events.
 // GET /api/v1/notifications/stream
 func (s *Server) handleNotificationStream(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -110,6 +107,7 @@ func (s *Server) handleNotificationStream(w http.ResponseWriter, r *http.Request
 	// Create subscriber
 	subscriberID := fmt.Sprintf("notification-sse-%d", time.Now().UnixNano())
 	subscriber := notificationMgr.Subscribe(user.ID, subscriberID)
+	counts := notificationMgr.SubscribeCounts(user.ID, subscriberID)
 	defer notificationMgr.Unsubscribe(user.ID, subscriberID)
 
 	// Send initial connection event
@@ -141,6 +139,22 @@ func (s *Server) handleNotificationStream(w http.ResponseWriter, r *http.Request
 			fmt.Fprintf(w, "event: notification\n")
 			fmt.Fprintf(w, "data: %s\n\n", data)
 
+			if flusher, ok := w.(http.Flusher); ok {
+				flusher.Flush()
+			}
+		case deltas, ok := <-counts:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(map[string]interface{}{"deltas": deltas})
+			if err != nil {
+				continue
+			}
+
+			fmt.Fprintf(w, "event: unread_counts\n")
+			fmt.Fprintf(w, "data: %s\n\n", data)
+
 			if flusher, ok := w.(http.Flusher); ok {
 				flusher.Flush()
 			}
@@ -156,6 +170,9 @@ func (s *Server) handleNotificationStream(w http.ResponseWriter, r *http.Request
 
 // handleNotificationActions handles notification action requests
 // POST /api/v1/notifications/{id}/read
+// POST /api/v1/notifications/{id}/archive
+// POST /api/v1/notifications/{id}/snooze {"hours": 4}
+// POST /api/v1/notifications/{id}/acknowledge
 func (s *Server) handleNotificationActions(w http.ResponseWriter, r *http.Request) {
 	notificationMgr := s.app.GetNotificationManager()
 	if notificationMgr == nil {
@@ -196,6 +213,48 @@ func (s *Server) handleNotificationActions(w http.ResponseWriter, r *http.Reques
 			"message": "Notification marked as read",
 		})
 
+	case "archive":
+		if err := notificationMgr.Archive(notificationID, user.ID); err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Notification archived",
+		})
+
+	case "snooze":
+		var req struct {
+			Hours float64 `json:"hours"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if req.Hours <= 0 {
+			s.respondError(w, http.StatusBadRequest, "hours must be positive")
+			return
+		}
+		until, err := notificationMgr.Snooze(notificationID, user.ID, time.Duration(req.Hours*float64(time.Hour)))
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message":       "Notification snoozed",
+			"snoozed_until": until,
+		})
+
+	case "acknowledge":
+		escalation, err := notificationMgr.Acknowledge(notificationID, user.ID)
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message":    "Notification acknowledged",
+			"escalation": escalation,
+		})
+
 	default:
 		s.respondError(w, http.StatusBadRequest, "Invalid action")
 	}
@@ -232,6 +291,35 @@ func (s *Server) handleMarkAllRead(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleUnreadCounts returns how many of the caller's notifications are
+// unread, in all, by priority and by project
+// GET /api/v1/notifications/unread-counts
+func (s *Server) handleUnreadCounts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	counts, err := notificationMgr.UnreadCounts(user.ID)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get unread counts: %v", err))
+		return
+	}
+	s.respondJSON(w, http.StatusOK, counts)
+}
+
 // handleNotificationPreferences handles notification preferences requests
 // GET /api/v1/notifications/preferences
 // PATCH /api/v1/notifications/preferences
@@ -284,6 +372,9 @@ func (s *Server) handleNotificationPreferences(w http.ResponseWriter, r *http.Re
 		if updates.EnableWebhook != prefs.EnableWebhook {
 			prefs.EnableWebhook = updates.EnableWebhook
 		}
+		if updates.EnablePush != prefs.EnablePush {
+			prefs.EnablePush = updates.EnablePush
+		}
 		if len(updates.SubscribedEvents) > 0 {
 			prefs.SubscribedEvents = updates.SubscribedEvents
 		}
@@ -296,12 +387,32 @@ func (s *Server) handleNotificationPreferences(w http.ResponseWriter, r *http.Re
 		if updates.QuietHoursEnd != "" {
 			prefs.QuietHoursEnd = updates.QuietHoursEnd
 		}
+		if updates.QuietSchedule != nil {
+			prefs.QuietSchedule = updates.QuietSchedule
+		}
+		if updates.Timezone != "" {
+			prefs.Timezone = updates.Timezone
+		}
+		if updates.QuietHoursAllowCritical != prefs.QuietHoursAllowCritical {
+			prefs.QuietHoursAllowCritical = updates.QuietHoursAllowCritical
+		}
 		if len(updates.ProjectFilters) > 0 {
 			prefs.ProjectFilters = updates.ProjectFilters
 		}
 		if updates.MinPriority != "" {
 			prefs.MinPriority = updates.MinPriority
 		}
+		if updates.Channels != nil {
+			prefs.Channels = updates.Channels
+		}
+		if updates.MuteRules != nil {
+			prefs.MuteRules = updates.MuteRules
+		}
+
+		if err := prefs.Validate(); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid preferences: %v", err))
+			return
+		}
 
 		// Save updates
 		if err := notificationMgr.UpdatePreferences(prefs); err != nil {
@@ -315,3 +426,367 @@ func (s *Server) handleNotificationPreferences(w http.ResponseWriter, r *http.Re
 		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
 }
+
+// handleNotificationMutes manages the caller's notification mute rules
+// GET /api/v1/notifications/mutes
+// POST /api/v1/notifications/mutes {"scope": "bead", "value": "bead-123", "hours": 24}
+// DELETE /api/v1/notifications/mutes/{id}
+func (s *Server) handleNotificationMutes(w http.ResponseWriter, r *http.Request) {
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	ruleID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/notifications/mutes"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && ruleID == "":
+		rules, err := notificationMgr.MuteRules(user.ID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get mute rules: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"mute_rules": rules,
+			"count":      len(rules),
+		})
+
+	case r.Method == http.MethodPost && ruleID == "":
+		var req struct {
+			Scope string  `json:"scope"`
+			Value string  `json:"value"`
+			Hours float64 `json:"hours"` // 0 mutes until the rule is removed
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if req.Hours < 0 {
+			s.respondError(w, http.StatusBadRequest, "hours must not be negative")
+			return
+		}
+		rule := &notifications.MuteRule{Scope: req.Scope, Value: req.Value}
+		if req.Hours > 0 {
+			until := time.Now().Add(time.Duration(req.Hours * float64(time.Hour)))
+			rule.Until = &until
+		}
+		rule, err := notificationMgr.AddMuteRule(user.ID, rule)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid mute rule: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusCreated, rule)
+
+	case r.Method == http.MethodDelete && ruleID != "":
+		if err := notificationMgr.RemoveMuteRule(user.ID, ruleID); err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Mute rule removed",
+		})
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleNotificationDevices manages the devices the caller receives push
+// notifications on
+// GET /api/v1/notifications/devices
+// POST /api/v1/notifications/devices {"platform": "fcm", "token": "...", "name": "Pixel 8"}
+// DELETE /api/v1/notifications/devices/{id}
+func (s *Server) handleNotificationDevices(w http.ResponseWriter, r *http.Request) {
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	deviceID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/notifications/devices"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && deviceID == "":
+		devices, err := notificationMgr.Devices(user.ID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get devices: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"devices": devices,
+			"count":   len(devices),
+		})
+
+	case r.Method == http.MethodPost && deviceID == "":
+		var req struct {
+			Platform string `json:"platform"` // fcm or apns
+			Token    string `json:"token"`
+			Name     string `json:"name"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		device, err := notificationMgr.RegisterDevice(user.ID, req.Platform, req.Token, req.Name)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid device: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusCreated, device)
+
+	case r.Method == http.MethodDelete && deviceID != "":
+		if err := notificationMgr.RemoveDevice(user.ID, deviceID); err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Device removed",
+		})
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleNotificationEscalations lists escalations of unacknowledged
+// notifications. Callers confined to an organization see only its
+// projects' escalations.
+// GET /api/v1/notifications/escalations?project_id=p&status=pending&limit=50
+func (s *Server) handleNotificationEscalations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+	if s.getUserFromContext(r) == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	limit := 50
+	if v := query.Get("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	var projectIDs []string
+	if projectID := query.Get("project_id"); projectID != "" {
+		if !inRequestOrg(r, s.app.ProjectOrg(projectID)) {
+			s.respondError(w, http.StatusNotFound, "Project not found")
+			return
+		}
+		projectIDs = []string{projectID}
+	} else if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		for _, p := range s.app.GetProjectManager().ListProjectsInOrg(scope) {
+			projectIDs = append(projectIDs, p.ID)
+		}
+		if len(projectIDs) == 0 {
+			s.respondJSON(w, http.StatusOK, map[string]interface{}{
+				"escalations": []*notifications.Escalation{},
+				"count":       0,
+			})
+			return
+		}
+	}
+	escalations, err := notificationMgr.Escalations(projectIDs, query.Get("status"), limit)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list escalations: %v", err))
+		return
+	}
+	s.respondJSON(w, http.StatusOK, map[string]interface{}{
+		"escalations": escalations,
+		"count":       len(escalations),
+	})
+}
+
+// handleOnCallChain manages a project's on-call chain. Callers confined to
+// an organization reach only its projects' chains.
+// GET /api/v1/notifications/oncall/{project_id}
+// PUT /api/v1/notifications/oncall/{project_id} {"user_ids": ["alice", "bob"], "ack_window_minutes": 15} (admin only)
+// DELETE /api/v1/notifications/oncall/{project_id} (admin only)
+func (s *Server) handleOnCallChain(w http.ResponseWriter, r *http.Request) {
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	projectID := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/v1/notifications/oncall"), "/")
+	if projectID == "" {
+		s.respondError(w, http.StatusBadRequest, "project_id is required")
+		return
+	}
+	if !inRequestOrg(r, s.app.ProjectOrg(projectID)) {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		chain, err := notificationMgr.OnCallChain(projectID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get on-call chain: %v", err))
+			return
+		}
+		if chain == nil {
+			s.respondError(w, http.StatusNotFound, fmt.Sprintf("on-call chain not found: %s", projectID))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, chain)
+
+	case http.MethodPut:
+		var chain notifications.OnCallChain
+		if err := json.NewDecoder(r.Body).Decode(&chain); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		chain.ProjectID = projectID
+		chain.UpdatedBy = user.ID
+		if err := notificationMgr.SetOnCallChain(&chain); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid on-call chain: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, chain)
+
+	case http.MethodDelete:
+		if err := notificationMgr.RemoveOnCallChain(projectID); err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "On-call chain removed",
+		})
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleNotificationTemplates handles notification template customization
+// GET /api/v1/notifications/templates?org_id=default[&event_type=...&channel=...]
+// PUT /api/v1/notifications/templates (admin only)
+// DELETE /api/v1/notifications/templates?event_type=...&channel=... (admin only)
+func (s *Server) handleNotificationTemplates(w http.ResponseWriter, r *http.Request) {
+	notificationMgr := s.app.GetNotificationManager()
+	if notificationMgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Notification manager not available")
+		return
+	}
+
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	query := r.URL.Query()
+	orgID := query.Get("org_id")
+	if orgID == "" {
+		orgID = org.DefaultID
+	}
+	// Callers confined to an organization customize only its templates
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		if query.Get("org_id") != "" && orgID != scope {
+			s.respondError(w, http.StatusNotFound, "Organization not found")
+			return
+		}
+		orgID = scope
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		eventType := query.Get("event_type")
+		channel := query.Get("channel")
+		if eventType != "" {
+			if channel == "" {
+				channel = notifications.ChannelInApp
+			}
+			tmpl, err := notificationMgr.GetTemplate(orgID, eventType, channel)
+			if err != nil {
+				s.respondError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			s.respondJSON(w, http.StatusOK, tmpl)
+			return
+		}
+
+		templates, err := notificationMgr.ListTemplates(orgID)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list templates: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"org_id":    orgID,
+			"templates": templates,
+			"count":     len(templates),
+		})
+
+	case http.MethodPut:
+		if user.Role != "admin" {
+			s.respondError(w, http.StatusForbidden, "Forbidden: admin access required")
+			return
+		}
+
+		var tmpl notifications.NotificationTemplate
+		if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+		if tmpl.OrgID == "" || auth.GetOrgIDFromRequest(r) != "" {
+			tmpl.OrgID = orgID
+		}
+		tmpl.UpdatedBy = user.ID
+
+		if err := notificationMgr.SetTemplate(&tmpl); err != nil {
+			s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid template: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, tmpl)
+
+	case http.MethodDelete:
+		if user.Role != "admin" {
+			s.respondError(w, http.StatusForbidden, "Forbidden: admin access required")
+			return
+		}
+
+		eventType := query.Get("event_type")
+		channel := query.Get("channel")
+		if eventType == "" || channel == "" {
+			s.respondError(w, http.StatusBadRequest, "event_type and channel are required")
+			return
+		}
+		if err := notificationMgr.DeleteTemplate(orgID, eventType, channel); err != nil {
+			s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete template: %v", err))
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{
+			"message": "Template reset to default",
+		})
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// handleProjectOnboardingScan scans a project's repository and files the
+// proposed onboarding backlog
+// POST /api/v1/projects/{id}/onboarding-scan
+func (s *Server) handleProjectOnboardingScan(w http.ResponseWriter, r *http.Request, projectID string) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req models.OnboardingScanRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if req.MaxBeads < 0 {
+		s.respondError(w, http.StatusBadRequest, "max_beads must not be negative")
+		return
+	}
+
+	report, err := s.app.ScanProjectForOnboarding(r.Context(), projectID, s.reviewerID(r), req.MaxBeads, req.DryRun)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "project not found"):
+			s.respondError(w, http.StatusNotFound, err.Error())
+		case strings.Contains(err.Error(), "already running"):
+			s.respondError(w, http.StatusConflict, err.Error())
+		case strings.Contains(err.Error(), "no working copy"):
+			s.respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	s.respondJSON(w, http.StatusOK, report)
+}
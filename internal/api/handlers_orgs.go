@@ -0,0 +1,149 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// inRequestOrg reports whether a record of organization orgID is visible to
+// the caller. Callers confined to an organization see only its records.
+func inRequestOrg(r *http.Request, orgID string) bool {
+	scope := auth.GetOrgIDFromRequest(r)
+	return scope == "" || org.Normalize(orgID) == scope
+}
+
+// createOrg picks the organization a record created by the caller belongs
+// to: the caller's own when they are confined to one, otherwise the
+// requested organization, which must exist
+func (s *Server) createOrg(r *http.Request, requested string) (string, error) {
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		if requested != "" && requested != scope {
+			return "", fmt.Errorf("cannot create records in another organization")
+		}
+		return scope, nil
+	}
+	requested = org.Normalize(requested)
+	if !s.app.GetOrgManager().Exists(requested) {
+		return "", fmt.Errorf("organization not found: %s", requested)
+	}
+	return requested, nil
+}
+
+// handleOrgs handles GET/POST /api/v1/orgs. Callers confined to an
+// organization list only their own and cannot create organizations.
+func (s *Server) handleOrgs(w http.ResponseWriter, r *http.Request) {
+	mgr := s.app.GetOrgManager()
+
+	switch r.Method {
+	case http.MethodGet:
+		list := mgr.List()
+		if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+			own := list[:0]
+			for _, o := range list {
+				if o.ID == scope {
+					own = append(own, o)
+				}
+			}
+			list = own
+		}
+		s.respondJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		if auth.GetOrgIDFromRequest(r) != "" {
+			s.respondError(w, http.StatusForbidden, "Only the default organization can manage organizations")
+			return
+		}
+		var req org.Request
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		o, err := mgr.Create(req)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditOrganization(r, "org.create", o)
+		s.respondJSON(w, http.StatusCreated, o)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleOrg serves one organization
+// GET    /api/v1/orgs/{id} - Get an organization
+// PUT    /api/v1/orgs/{id} - Rename it or change its daily budget
+// DELETE /api/v1/orgs/{id} - Delete an organization that no longer owns projects or users
+// /api/v1/orgs/{id}/lessons/... - The organization's shared lessons
+func (s *Server) handleOrg(w http.ResponseWriter, r *http.Request) {
+	mgr := s.app.GetOrgManager()
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/v1/orgs/"), "/")
+	var sub []string
+	if parts := strings.Split(id, "/"); len(parts) > 1 && parts[1] == "lessons" {
+		id, sub = parts[0], parts[2:]
+	}
+	if id == "" || strings.Contains(id, "/") {
+		s.respondError(w, http.StatusNotFound, "Organization not found")
+		return
+	}
+
+	o, err := mgr.Get(id)
+	if err != nil || !inRequestOrg(r, o.ID) {
+		s.respondError(w, http.StatusNotFound, "Organization not found")
+		return
+	}
+	// Members curate their own organization's shared lessons
+	if sub != nil {
+		s.handleOrgLessons(w, r, o.ID, sub)
+		return
+	}
+	if r.Method != http.MethodGet && auth.GetOrgIDFromRequest(r) != "" {
+		s.respondError(w, http.StatusForbidden, "Only the default organization can manage organizations")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.respondJSON(w, http.StatusOK, o)
+
+	case http.MethodPut:
+		var req org.Request
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		updated, err := mgr.Update(o.ID, req)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditOrganization(r, "org.update", updated)
+		s.respondJSON(w, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if n := len(s.app.GetProjectManager().ListProjectsInOrg(o.ID)); n > 0 {
+			s.respondError(w, http.StatusConflict, fmt.Sprintf("Organization still owns %d projects", n))
+			return
+		}
+		if s.authManager != nil {
+			if n := len(s.authManager.ListUsersInOrg(o.ID)); n > 0 {
+				s.respondError(w, http.StatusConflict, fmt.Sprintf("Organization still has %d users", n))
+				return
+			}
+		}
+		if err := mgr.Delete(o.ID); err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditOrganization(r, "org.delete", o)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
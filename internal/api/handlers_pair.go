@@ -165,8 +165,9 @@ func (s *Server) handlePairChat(w http.ResponseWriter, r *http.Request) {
 		Stream:      true,
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	// Create context with timeout; the caller is waiting, so the request
+	// skips ahead of agent work on a saturated provider
+	ctx, cancel := context.WithTimeout(provider.WithPriority(r.Context(), provider.PriorityP0), 5*time.Minute)
 	defer cancel()
 
 	var streamedText strings.Builder
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// handleProjectPatches serves the agent commits a project holds for
+// approval
+// GET  /api/v1/projects/{id}/patches                        - Patches, newest first; ?status= filters
+// POST /api/v1/projects/{id}/patches/approve-low-risk       - Approve every pending low-risk patch
+// GET  /api/v1/projects/{id}/patches/{patch_id}             - A patch with its diff
+// POST /api/v1/projects/{id}/patches/{patch_id}/approve     - Approve a patch
+// POST /api/v1/projects/{id}/patches/{patch_id}/reject      - Reject a patch with a reason
+func (s *Server) handleProjectPatches(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 0:
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		patches, err := s.app.ListPatches(projectID, models.PatchStatus(r.URL.Query().Get("status")))
+		if err != nil {
+			s.respondPatchError(w, err)
+			return
+		}
+		if patches == nil {
+			patches = []*models.ProposedPatch{}
+		}
+		s.respondJSON(w, http.StatusOK, patches)
+
+	case len(parts) == 1 && parts[0] == "approve-low-risk":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		var req models.ApproveLowRiskPatchesRequest
+		if r.ContentLength != 0 {
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+		}
+		approved, err := s.app.ApproveLowRiskPatches(projectID, s.reviewerID(r), req.Globs)
+		if err != nil {
+			s.respondPatchError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, approved)
+
+	case len(parts) <= 2:
+		patch, err := s.app.GetPatch(parts[0])
+		if err != nil || patch.ProjectID != projectID {
+			s.respondError(w, http.StatusNotFound, "Patch not found")
+			return
+		}
+		action := ""
+		if len(parts) == 2 {
+			action = parts[1]
+		}
+		switch {
+		case action == "" && r.Method == http.MethodGet:
+		case action == "approve" && r.Method == http.MethodPost:
+			patch, err = s.app.ApprovePatch(patch.ID, s.reviewerID(r))
+		case action == "reject" && r.Method == http.MethodPost:
+			var req models.RejectPatchRequest
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			patch, err = s.app.RejectPatch(patch.ID, s.reviewerID(r), req.Reason)
+		case action == "" || action == "approve" || action == "reject":
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		default:
+			s.respondError(w, http.StatusNotFound, "Not found")
+			return
+		}
+		if err != nil {
+			s.respondPatchError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, patch)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// respondPatchError maps a commit approval error to its status code
+func (s *Server) respondPatchError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.Contains(err.Error(), "not found"):
+		s.respondError(w, http.StatusNotFound, err.Error())
+	case strings.Contains(err.Error(), "not configured"):
+		s.respondError(w, http.StatusServiceUnavailable, err.Error())
+	case strings.Contains(err.Error(), "already"), strings.Contains(err.Error(), "required"), strings.Contains(err.Error(), "no low-risk globs"):
+		s.respondError(w, http.StatusBadRequest, err.Error())
+	default:
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -0,0 +1,215 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// PersonaRequest creates a persona or publishes a new version of its
+// definition. Name is only read on create.
+type PersonaRequest struct {
+	Name string `json:"name,omitempty"`
+	persona.Definition
+	Comment string `json:"comment,omitempty"`
+}
+
+// PersonaRollbackRequest makes an older version of a persona's definition
+// active again
+type PersonaRollbackRequest struct {
+	Version int    `json:"version"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// handlePersonas handles GET/POST /api/v1/personas
+func (s *Server) handlePersonas(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		personas, err := s.app.GetPersonaManager().ListPersonas()
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+
+		// Load full persona details
+		fullPersonas := make([]*models.Persona, 0, len(personas))
+		for _, name := range personas {
+			persona, err := s.app.GetPersonaManager().LoadPersona(name)
+			if err != nil {
+				continue
+			}
+			fullPersonas = append(fullPersonas, persona)
+		}
+
+		s.respondJSON(w, http.StatusOK, fullPersonas)
+
+	case http.MethodPost:
+		var req PersonaRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if err := persona.ValidateName(req.Name); err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if _, err := s.app.GetPersonaManager().LoadPersona(req.Name); err == nil {
+			s.respondError(w, http.StatusConflict, "Persona already exists: "+req.Name)
+			return
+		}
+		s.publishPersona(w, r, req.Name, req, http.StatusCreated)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handlePersona serves one persona and its definition versions. Names may
+// contain slashes, e.g. default/qa-engineer.
+// GET    /api/v1/personas/{name}                     - The persona with its active definition
+// PUT    /api/v1/personas/{name}                     - Publish a new definition version
+// DELETE /api/v1/personas/{name}                     - Remove the published versions
+// GET    /api/v1/personas/{name}/versions            - All versions, newest first
+// GET    /api/v1/personas/{name}/versions/{version}  - One version
+// POST   /api/v1/personas/{name}/rollback            - Republish an older version
+func (s *Server) handlePersona(w http.ResponseWriter, r *http.Request) {
+	name, action, version := parsePersonaPath(strings.TrimPrefix(r.URL.Path, "/api/v1/personas/"))
+	if name == "" {
+		s.respondError(w, http.StatusNotFound, "Persona not found")
+		return
+	}
+
+	switch action {
+	case "versions":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		if version == "" {
+			versions, err := s.app.GetPersonaManager().Versions(name)
+			if err != nil {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			s.respondJSON(w, http.StatusOK, versions)
+			return
+		}
+		n, err := strconv.Atoi(version)
+		if err != nil || n <= 0 {
+			s.respondError(w, http.StatusBadRequest, "version must be a positive integer")
+			return
+		}
+		v, err := s.app.GetPersonaManager().Version(name, n)
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, v)
+		return
+
+	case "rollback":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		var req PersonaRollbackRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if _, err := s.app.GetPersonaManager().Version(name, req.Version); err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		createdBy := ""
+		if user := s.getUserFromContext(r); user != nil {
+			createdBy = user.ID
+		}
+		v, err := s.app.RollbackPersona(name, req.Version, req.Comment, createdBy)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.auditPersona(r, "persona.rollback", name, v)
+		s.respondJSON(w, http.StatusOK, v)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		persona, err := s.app.GetPersonaManager().LoadPersona(name)
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, "Persona not found")
+			return
+		}
+		s.respondJSON(w, http.StatusOK, persona)
+
+	case http.MethodPut:
+		var req PersonaRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if _, err := s.app.GetPersonaManager().LoadPersona(name); err != nil {
+			s.respondError(w, http.StatusNotFound, "Persona not found")
+			return
+		}
+		s.publishPersona(w, r, name, req, http.StatusOK)
+
+	case http.MethodDelete:
+		versions, err := s.app.GetPersonaManager().Versions(name)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if len(versions) == 0 {
+			s.respondError(w, http.StatusNotFound, "Persona has no published versions")
+			return
+		}
+		if err := s.app.DeletePersona(name); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.auditPersona(r, "persona.delete", name, versions[0])
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// publishPersona publishes req as the persona's next definition version
+func (s *Server) publishPersona(w http.ResponseWriter, r *http.Request, name string, req PersonaRequest, status int) {
+	createdBy := ""
+	if user := s.getUserFromContext(r); user != nil {
+		createdBy = user.ID
+	}
+	v, err := s.app.PublishPersona(name, req.Definition, req.Comment, createdBy)
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.auditPersona(r, "persona.publish", name, v)
+	s.respondJSON(w, status, v)
+}
+
+// parsePersonaPath splits the path after /api/v1/personas/ into the
+// persona name, the versions or rollback action if any, and the version
+func parsePersonaPath(path string) (name, action, version string) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		switch {
+		case part == "rollback" && i == len(parts)-1:
+			return strings.Join(parts[:i], "/"), part, ""
+		case part == "versions" && i >= len(parts)-2:
+			if i == len(parts)-2 {
+				version = parts[i+1]
+			}
+			return strings.Join(parts[:i], "/"), part, version
+		}
+	}
+	return strings.Join(parts, "/"), "", ""
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// handleBeadPlan serves the execution plan of a bead in plan mode and its
+// review
+// GET  /api/v1/beads/{id}/plan
+// POST /api/v1/beads/{id}/plan/approve
+// POST /api/v1/beads/{id}/plan/reject
+func (s *Server) handleBeadPlan(w http.ResponseWriter, r *http.Request, beadID string, rest []string) {
+	action := ""
+	if len(rest) > 0 {
+		action = rest[0]
+	}
+	var (
+		plan *models.ExecutionPlan
+		err  error
+	)
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		plan, err = s.app.GetBeadPlan(beadID)
+	case action == "approve" && r.Method == http.MethodPost:
+		plan, err = s.app.ApproveBeadPlan(beadID, s.reviewerID(r))
+	case action == "reject" && r.Method == http.MethodPost:
+		var req models.RejectPlanRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		plan, err = s.app.RejectBeadPlan(beadID, s.reviewerID(r), req.Reason)
+	case action == "" || action == "approve" || action == "reject":
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"), strings.Contains(err.Error(), "no execution plan"):
+			s.respondError(w, http.StatusNotFound, err.Error())
+		case strings.Contains(err.Error(), "already"), strings.Contains(err.Error(), "required"):
+			s.respondError(w, http.StatusBadRequest, err.Error())
+		default:
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	s.respondJSON(w, http.StatusOK, plan)
+}
+
+// reviewerID names who reviews a plan or patch, or undoes a bead, for
+// its record
+func (s *Server) reviewerID(r *http.Request) string {
+	if user := s.getUserFromContext(r); user != nil {
+		return user.ID
+	}
+	return "anonymous"
+}
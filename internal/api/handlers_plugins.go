@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// handlePluginPanels handles GET /api/v1/plugins/panels, listing the
+// dashboard panels of every plugin
+func (s *Server) handlePluginPanels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, s.app.GetPanelRegistry().List())
+}
+
+// handlePlugin serves the panels one plugin contributes, namespaced by the
+// plugin's provider type
+// GET /api/v1/plugins/{id}/panels            - List the plugin's panels
+// GET /api/v1/plugins/{id}/panels/{panel_id} - Fetch a panel's data
+func (s *Server) handlePlugin(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/plugins/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] != "panels" {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	panels := s.app.GetPanelRegistry()
+
+	if len(parts) == 2 {
+		list, err := panels.ListNamespace(parts[0])
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, list)
+		return
+	}
+
+	params := make(map[string]string)
+	for k, v := range r.URL.Query() {
+		if len(v) > 0 {
+			params[k] = v[0]
+		}
+	}
+	data, err := panels.Data(r.Context(), parts[0], parts[2], params)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		// The plugin failed or broke its schema
+		s.respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, data)
+}
@@ -0,0 +1,167 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/jordanhubbard/loom/internal/policy"
+)
+
+// ProjectPolicy is a project's active policy version and the global policy
+// it is layered over
+type ProjectPolicy struct {
+	ProjectID string           `json:"project_id"`
+	Active    *policy.Version  `json:"active,omitempty"` // Nil until the project publishes a version
+	Global    *policy.Document `json:"global,omitempty"`
+}
+
+// PolicyRequest publishes a project policy version. Source, a YAML or JSON
+// policy document, takes the place of Document when set.
+type PolicyRequest struct {
+	Document policy.Document `json:"document"`
+	Source   string          `json:"source,omitempty"`
+	Comment  string          `json:"comment,omitempty"`
+}
+
+// PolicyEvaluateRequest is a dry run: the kind and attributes to decide,
+// and optionally a draft document, as structure or YAML/JSON source, to try
+// in place of the project's active version
+type PolicyEvaluateRequest struct {
+	Kind        string                 `json:"kind"`
+	Attributes  map[string]interface{} `json:"attributes,omitempty"`
+	Draft       *policy.Document       `json:"draft,omitempty"`
+	DraftSource string                 `json:"draft_source,omitempty"`
+}
+
+// handleProjectPolicy serves a project's versioned policy
+// GET  /api/v1/projects/{id}/policy                     - Active version and global policy
+// PUT  /api/v1/projects/{id}/policy                     - Publish a new version
+// GET  /api/v1/projects/{id}/policy/versions            - All versions, newest first
+// GET  /api/v1/projects/{id}/policy/versions/{version}  - One version
+// POST /api/v1/projects/{id}/policy/evaluate            - Dry-run evaluation
+func (s *Server) handleProjectPolicy(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	engine := s.app.GetPolicyEngine()
+	if engine == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Policy engine not available")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 0:
+		switch r.Method {
+		case http.MethodGet:
+			active, err := engine.Active(projectID)
+			if err != nil {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			s.respondJSON(w, http.StatusOK, ProjectPolicy{ProjectID: projectID, Active: active, Global: engine.Global()})
+
+		case http.MethodPut:
+			var req PolicyRequest
+			if err := s.parseJSON(r, &req); err != nil {
+				s.respondError(w, http.StatusBadRequest, "Invalid request body")
+				return
+			}
+			doc := req.Document
+			if req.Source != "" {
+				parsed, err := policy.Parse([]byte(req.Source))
+				if err != nil {
+					s.respondError(w, http.StatusBadRequest, err.Error())
+					return
+				}
+				doc = *parsed
+			}
+			if err := doc.Validate(); err != nil {
+				s.respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			createdBy := ""
+			if user := s.getUserFromContext(r); user != nil {
+				createdBy = user.ID
+			}
+			v, err := engine.Publish(projectID, doc, req.Comment, createdBy)
+			if errors.Is(err, policy.ErrNoStore) {
+				s.respondError(w, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+			if err != nil {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			s.auditPolicyPublish(r, v)
+			s.respondJSON(w, http.StatusOK, v)
+
+		default:
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		}
+
+	case parts[0] == "versions" && len(parts) <= 2:
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		if len(parts) == 1 {
+			versions, err := engine.Versions(projectID)
+			if errors.Is(err, policy.ErrNoStore) {
+				s.respondError(w, http.StatusServiceUnavailable, err.Error())
+				return
+			}
+			if err != nil {
+				s.respondError(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+			s.respondJSON(w, http.StatusOK, versions)
+			return
+		}
+		n, err := strconv.Atoi(parts[1])
+		if err != nil || n <= 0 {
+			s.respondError(w, http.StatusBadRequest, "version must be a positive integer")
+			return
+		}
+		v, err := engine.Version(projectID, n)
+		if err != nil {
+			s.respondError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, v)
+
+	case parts[0] == "evaluate" && len(parts) == 1:
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		var req PolicyEvaluateRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		draft := req.Draft
+		if req.DraftSource != "" {
+			parsed, err := policy.Parse([]byte(req.DraftSource))
+			if err != nil {
+				s.respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			draft = parsed
+		}
+		decision, err := engine.DryRun(r.Context(), policy.Input{
+			Kind:       req.Kind,
+			ProjectID:  projectID,
+			Attributes: req.Attributes,
+		}, draft)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, decision)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Unknown policy action")
+	}
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/prreview"
+)
+
+// prReviewsDefaultLimit is how many reviews are listed without ?limit
+const prReviewsDefaultLimit = 20
+
+// handleProjectPRReviews serves a project's automated pull request reviews
+// GET  /api/v1/projects/{id}/pr-reviews              - Recent reviews, newest first
+// POST /api/v1/projects/{id}/pr-reviews              - Review a pull request now
+// GET  /api/v1/projects/{id}/pr-reviews/{review_id}  - One review
+func (s *Server) handleProjectPRReviews(w http.ResponseWriter, r *http.Request, projectID string, parts []string) {
+	if len(parts) > 0 && parts[len(parts)-1] == "" {
+		parts = parts[:len(parts)-1]
+	}
+	switch {
+	case len(parts) == 0 && r.Method != http.MethodGet && r.Method != http.MethodPost,
+		len(parts) == 1 && r.Method != http.MethodGet:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	case len(parts) > 1:
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	mgr := s.app.GetPRReviews()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Pull request reviews not available")
+		return
+	}
+	if _, err := s.app.GetProjectManager().GetProject(projectID); err != nil {
+		s.respondError(w, http.StatusNotFound, "Project not found")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		review, err := mgr.Get(projectID, parts[0])
+		if err != nil {
+			s.respondPRReviewError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, review)
+
+	case r.Method == http.MethodGet:
+		limit := prReviewsDefaultLimit
+		if v := r.URL.Query().Get("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+				return
+			}
+			limit = n
+		}
+		reviews, err := mgr.List(projectID, limit)
+		if err != nil {
+			s.respondPRReviewError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, reviews)
+
+	default:
+		var req prreview.PullRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		req.ProjectID = projectID
+		// A review that could not complete is recorded with status error
+		// and returned as is
+		review, err := mgr.Review(r.Context(), req)
+		if review == nil {
+			s.respondPRReviewError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, review)
+	}
+}
+
+// respondPRReviewError maps pull request review errors to status codes
+func (s *Server) respondPRReviewError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.Contains(msg, "already under review"):
+		s.respondError(w, http.StatusConflict, msg)
+	case strings.Contains(msg, "must"):
+		s.respondError(w, http.StatusBadRequest, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+)
+
+// ProjectTemplateRequest creates a project template. Callers confined to an
+// organization always create it in their own; others may name one, and
+// otherwise share the template with every organization.
+type ProjectTemplateRequest struct {
+	projecttemplates.Request
+	OrgID string `json:"org_id,omitempty"`
+}
+
+// templateVisible reports whether the caller may see a template: shared
+// templates are visible to everyone, the rest to their organization
+func templateVisible(r *http.Request, t *projecttemplates.Template) bool {
+	return t.OrgID == "" || inRequestOrg(r, t.OrgID)
+}
+
+// handleProjectTemplates handles GET/POST /api/v1/project-templates
+func (s *Server) handleProjectTemplates(w http.ResponseWriter, r *http.Request) {
+	mgr := s.app.GetProjectTemplates()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Project templates not available")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := mgr.List()
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		visible := list[:0]
+		for _, t := range list {
+			if templateVisible(r, t) {
+				visible = append(visible, t)
+			}
+		}
+		s.respondJSON(w, http.StatusOK, visible)
+
+	case http.MethodPost:
+		var req ProjectTemplateRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		orgID := req.OrgID
+		if scope := auth.GetOrgIDFromRequest(r); scope != "" || orgID != "" {
+			var err error
+			if orgID, err = s.createOrg(r, orgID); err != nil {
+				s.respondError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+		createdBy := ""
+		if user := s.getUserFromContext(r); user != nil {
+			createdBy = user.ID
+		}
+		t, err := mgr.Create(orgID, req.Request, createdBy)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditProjectTemplate(r, "project_template.create", t, "")
+		s.respondJSON(w, http.StatusCreated, t)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleProjectTemplate serves one template and makes projects from it
+// GET    /api/v1/project-templates/{id}             - Get a template
+// PUT    /api/v1/project-templates/{id}             - Update a template
+// DELETE /api/v1/project-templates/{id}             - Delete a template
+// POST   /api/v1/project-templates/{id}/instantiate - Check the template with dry_run, or make a project from it
+func (s *Server) handleProjectTemplate(w http.ResponseWriter, r *http.Request) {
+	mgr := s.app.GetProjectTemplates()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Project templates not available")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/project-templates/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	if parts[0] == "" || len(parts) > 2 || (len(parts) == 2 && parts[1] != "instantiate") {
+		s.respondError(w, http.StatusNotFound, "Not found")
+		return
+	}
+
+	t, err := mgr.Get(parts[0])
+	if err != nil || !templateVisible(r, t) {
+		s.respondError(w, http.StatusNotFound, "Project template not found")
+		return
+	}
+	if len(parts) == 2 {
+		s.handleInstantiateProjectTemplate(w, r, t)
+		return
+	}
+	// Organization members may use shared templates but not change them
+	if r.Method != http.MethodGet && t.OrgID == "" && auth.GetOrgIDFromRequest(r) != "" {
+		s.respondError(w, http.StatusForbidden, "Shared templates can only be changed by the default organization")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		s.respondJSON(w, http.StatusOK, t)
+
+	case http.MethodPut:
+		var req projecttemplates.Request
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		updated, err := mgr.Update(t.ID, req)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditProjectTemplate(r, "project_template.update", updated, "")
+		s.respondJSON(w, http.StatusOK, updated)
+
+	case http.MethodDelete:
+		if err := mgr.Delete(t.ID); err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.auditProjectTemplate(r, "project_template.delete", t, "")
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleInstantiateProjectTemplate handles POST
+// /api/v1/project-templates/{id}/instantiate. A dry run reports what would
+// be created and any issues in the way; otherwise the project is made in
+// the caller's organization and the response carries its deploy key and
+// webhook secrets, which are not shown again.
+func (s *Server) handleInstantiateProjectTemplate(w http.ResponseWriter, r *http.Request, t *projecttemplates.Template) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req projecttemplates.InstantiateRequest
+	if err := s.parseJSON(r, &req); err != nil {
+		s.respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	orgID, err := s.createOrg(r, firstNonEmpty(req.OrgID, t.OrgID))
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.OrgID = orgID
+
+	plan := s.app.PlanProjectTemplate(t, req)
+	if req.DryRun {
+		s.respondJSON(w, http.StatusOK, plan)
+		return
+	}
+	if len(plan.Issues) > 0 {
+		s.respondError(w, http.StatusBadRequest, "Template cannot be instantiated: "+strings.Join(plan.Issues, "; "))
+		return
+	}
+
+	res, err := s.app.InstantiateProjectTemplate(r.Context(), t, req)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	s.auditProjectTemplate(r, "project_template.instantiate", t, res.Project.ID)
+	s.respondJSON(w, http.StatusCreated, res)
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
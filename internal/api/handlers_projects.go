@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 
+	"github.com/jordanhubbard/loom/internal/auth"
 	"github.com/jordanhubbard/loom/internal/project"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
@@ -315,5 +316,12 @@ func (s *Server) handleBootstrapProject(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Projects bootstrapped by organization members belong to their organization
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" && result.ProjectID != "" {
+		if err := s.app.GetProjectManager().UpdateProject(result.ProjectID, map[string]interface{}{"org_id": scope}); err == nil {
+			s.app.PersistProject(result.ProjectID)
+		}
+	}
+
 	s.respondJSON(w, http.StatusCreated, result)
 }
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+const (
+	// providerMetricsDefaultWindow is how far back history goes without ?since
+	providerMetricsDefaultWindow = 24 * time.Hour
+	// providerMetricsDefaultLimit is how many snapshots are returned without ?limit
+	providerMetricsDefaultLimit = 500
+)
+
+// ProviderMetricsResponse is a provider's current score and the history of
+// the scorer metrics behind it
+type ProviderMetricsResponse struct {
+	ProviderID string                  `json:"provider_id"`
+	Score      *provider.ProviderScore `json:"score,omitempty"` // Absent until the provider is scored
+	History    []provider.ScoreMetrics `json:"history"`         // Oldest first
+}
+
+// handleProviderMetrics handles GET /api/v1/providers/{id}/metrics: the
+// provider's current score and its metrics snapshots since ?since
+// (RFC 3339, 24 hours ago by default)
+func (s *Server) handleProviderMetrics(w http.ResponseWriter, r *http.Request, providerID string) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.app == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Application not initialized")
+		return
+	}
+
+	since := time.Now().Add(-providerMetricsDefaultWindow)
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, "since must be an RFC 3339 time")
+			return
+		}
+		since = t
+	}
+	limit := providerMetricsDefaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			s.respondError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	history, err := s.app.GetProviderMetricsHistory(providerID, since, limit)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if history == nil {
+		history = []provider.ScoreMetrics{}
+	}
+	resp := ProviderMetricsResponse{ProviderID: providerID, History: history}
+	if reg := s.app.GetProviderRegistry(); reg != nil {
+		if score, ok := reg.GetScorer().GetScore(providerID); ok {
+			resp.Score = score
+		}
+	}
+	s.respondJSON(w, http.StatusOK, resp)
+}
+
+// handleProviderQueue handles GET /api/v1/providers/{id}/queue: the
+// provider's request queue, per priority lane
+func (s *Server) handleProviderQueue(w http.ResponseWriter, r *http.Request, providerID string) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.app == nil || s.app.GetProviderRegistry() == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Application not initialized")
+		return
+	}
+	stats, ok := s.app.GetProviderRegistry().ProviderQueueStats(providerID)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Provider not registered")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, stats)
+}
+
+// handleProviderCapabilities handles GET /api/v1/providers/{id}/capabilities
+func (s *Server) handleProviderCapabilities(w http.ResponseWriter, r *http.Request, providerID string) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	if s.app == nil || s.app.GetProviderRegistry() == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Application not initialized")
+		return
+	}
+	caps, ok := s.app.GetProviderRegistry().Capabilities(providerID)
+	if !ok {
+		s.respondError(w, http.StatusNotFound, "Provider does not report its capabilities")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, caps)
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/database"
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
 )
 
@@ -18,6 +20,7 @@ type ProviderRequest struct {
 	APIKey      string `json:"api_key"`
 	Model       string `json:"model"`
 	Description string `json:"description"`
+	OrgID       string `json:"org_id"` // Reserve the provider for one organization; empty shares it
 }
 
 // handleProviders handles GET/POST /api/v1/providers
@@ -28,7 +31,13 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 			s.respondError(w, http.StatusServiceUnavailable, "Application not initialized")
 			return
 		}
-		providers, err := s.app.ListProviders()
+		var providers []*internalmodels.Provider
+		var err error
+		if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+			providers, err = s.app.ListProvidersInOrg(scope)
+		} else {
+			providers, err = s.app.ListProviders()
+		}
 		if err != nil {
 			s.respondError(w, http.StatusInternalServerError, err.Error())
 			return
@@ -41,6 +50,11 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 			s.respondError(w, http.StatusBadRequest, "Invalid request body")
 			return
 		}
+		orgID, err := s.providerOrg(r, req.OrgID)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 
 		provider := &internalmodels.Provider{
 			ID:          req.ID,
@@ -49,6 +63,7 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 			Endpoint:    req.Endpoint,
 			Model:       req.Model,
 			Description: req.Description,
+			OrgID:       orgID,
 		}
 
 		// Store API key if provided
@@ -81,7 +96,8 @@ func (s *Server) handleProviders(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleProvider handles GET/DELETE /api/v1/providers/{id} and GET /api/v1/providers/{id}/models
+// handleProvider handles GET/DELETE /api/v1/providers/{id}, GET /api/v1/providers/{id}/models
+// and /api/v1/providers/{id}/credentials/...
 func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/v1/providers/")
 	parts := strings.Split(path, "/")
@@ -92,6 +108,23 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Callers confined to an organization see its providers and the shared
+	// ones, and change only its own
+	var existing *internalmodels.Provider
+	if s.app != nil {
+		existing = s.findProvider(providerID)
+	}
+	if scope := auth.GetOrgIDFromRequest(r); scope != "" {
+		if existing == nil || (existing.OrgID != "" && existing.OrgID != scope) {
+			s.respondError(w, http.StatusNotFound, "Provider not found")
+			return
+		}
+		if !isReadMethod(r.Method) && existing.OrgID != scope {
+			s.respondError(w, http.StatusForbidden, "Shared providers can only be changed by the default organization")
+			return
+		}
+	}
+
 	if len(parts) > 1 && parts[1] == "models" {
 		if r.Method != http.MethodGet {
 			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -109,6 +142,38 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 		s.respondJSON(w, http.StatusOK, map[string]interface{}{"models": models})
 		return
 	}
+	if len(parts) > 1 && parts[1] == "metrics" {
+		if existing == nil {
+			s.respondError(w, http.StatusNotFound, "Provider not found")
+			return
+		}
+		s.handleProviderMetrics(w, r, providerID)
+		return
+	}
+	if len(parts) > 1 && parts[1] == "queue" {
+		if existing == nil {
+			s.respondError(w, http.StatusNotFound, "Provider not found")
+			return
+		}
+		s.handleProviderQueue(w, r, providerID)
+		return
+	}
+	if len(parts) > 1 && parts[1] == "capabilities" {
+		if existing == nil {
+			s.respondError(w, http.StatusNotFound, "Provider not found")
+			return
+		}
+		s.handleProviderCapabilities(w, r, providerID)
+		return
+	}
+	if len(parts) > 1 && parts[1] == "credentials" {
+		action := ""
+		if len(parts) > 2 {
+			action = parts[2]
+		}
+		s.handleProviderCredentials(w, r, providerID, action)
+		return
+	}
 	if len(parts) > 1 && parts[1] == "negotiate" {
 		if r.Method != http.MethodPost {
 			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
@@ -133,18 +198,11 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 			s.respondError(w, http.StatusServiceUnavailable, "Application not initialized")
 			return
 		}
-		providers, err := s.app.ListProviders()
-		if err != nil {
-			s.respondError(w, http.StatusInternalServerError, err.Error())
+		if existing == nil {
+			s.respondError(w, http.StatusNotFound, "Provider not found")
 			return
 		}
-		for _, p := range providers {
-			if p.ID == providerID {
-				s.respondJSON(w, http.StatusOK, p)
-				return
-			}
-		}
-		s.respondError(w, http.StatusNotFound, "Provider not found")
+		s.respondJSON(w, http.StatusOK, existing)
 
 	case http.MethodDelete:
 		if s.app == nil {
@@ -168,6 +226,16 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		req.ID = providerID
+		// Omitting org_id keeps the provider's organization
+		if req.OrgID == "" && existing != nil {
+			req.OrgID = existing.OrgID
+		}
+		orgID, err := s.providerOrg(r, req.OrgID)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		req.OrgID = orgID
 		updated, err := s.app.UpdateProvider(context.Background(), &req)
 		if err != nil {
 			s.respondError(w, http.StatusBadRequest, err.Error())
@@ -179,3 +247,107 @@ func (s *Server) handleProvider(w http.ResponseWriter, r *http.Request) {
 		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
 	}
 }
+
+// findProvider returns a registered provider, or nil when there is none
+func (s *Server) findProvider(providerID string) *internalmodels.Provider {
+	providers, err := s.app.ListProviders()
+	if err != nil {
+		return nil
+	}
+	for _, p := range providers {
+		if p.ID == providerID {
+			return p
+		}
+	}
+	return nil
+}
+
+// providerOrg picks the organization a provider is reserved for: the
+// caller's own when they are confined to one, otherwise the requested
+// organization, which must exist. "" shares the provider with every
+// organization.
+func (s *Server) providerOrg(r *http.Request, requested string) (string, error) {
+	if requested == "" && auth.GetOrgIDFromRequest(r) == "" {
+		return "", nil
+	}
+	return s.createOrg(r, requested)
+}
+
+// handleProviderCredentials handles provider credential rotation (admin only)
+// GET  /api/v1/providers/{id}/credentials           - rotation history
+// POST /api/v1/providers/{id}/credentials/rotate    - stage, verify and swap in one step
+// POST /api/v1/providers/{id}/credentials/stage     - store a new key without using it
+// POST /api/v1/providers/{id}/credentials/verify    - health-probe the staged key
+// POST /api/v1/providers/{id}/credentials/promote   - swap the verified key into service
+// POST /api/v1/providers/{id}/credentials/rollback  - restore the previous key during the grace period
+func (s *Server) handleProviderCredentials(w http.ResponseWriter, r *http.Request, providerID, action string) {
+	if s.app == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Application not initialized")
+		return
+	}
+
+	user := s.getUserFromContext(r)
+	if user == nil {
+		s.respondError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+	if user.Role != "admin" {
+		s.respondError(w, http.StatusForbidden, "Forbidden: admin access required")
+		return
+	}
+
+	if action == "" {
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		rotations, err := s.app.ListProviderCredentialRotations(providerID, 20)
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, map[string]interface{}{"rotations": rotations})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+
+	var rotation *database.KeyRotation
+	var err error
+	switch action {
+	case "rotate", "stage":
+		var req struct {
+			APIKey string `json:"api_key"`
+		}
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		if req.APIKey == "" {
+			s.respondError(w, http.StatusBadRequest, "api_key is required")
+			return
+		}
+		if action == "rotate" {
+			rotation, err = s.app.RotateProviderCredential(r.Context(), providerID, req.APIKey, user.ID)
+		} else {
+			rotation, err = s.app.StageProviderCredential(providerID, req.APIKey, user.ID)
+		}
+	case "verify":
+		rotation, err = s.app.VerifyProviderCredential(r.Context(), providerID)
+	case "promote":
+		rotation, err = s.app.PromoteProviderCredential(providerID)
+	case "rollback":
+		rotation, err = s.app.RollbackProviderCredential(providerID)
+	default:
+		s.respondError(w, http.StatusNotFound, "Unknown credential action")
+		return
+	}
+	if err != nil {
+		s.respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.respondJSON(w, http.StatusOK, rotation)
+}
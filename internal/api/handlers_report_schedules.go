@@ -0,0 +1,194 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/reports"
+)
+
+// reportRunListOptions pages a schedule's run log, newest first
+var reportRunListOptions = listOptions{defaultLimit: 50, sorts: []listSort{{"started_at", "ts"}}, defaultDesc: true}
+
+// ReportRunPage is the body of a run log page
+type ReportRunPage struct {
+	Runs       []reports.Run `json:"runs"`
+	Count      int           `json:"count"`
+	Total      int           `json:"total"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// reportScheduler returns the report scheduler, responding with an error
+// when there is none
+func (s *Server) reportScheduler(w http.ResponseWriter) (*reports.Manager, bool) {
+	mgr := s.app.GetReportScheduler()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Report scheduling not available")
+		return nil, false
+	}
+	return mgr, true
+}
+
+// handleReportSchedules handles GET/POST /api/v1/report-schedules. GET
+// takes an optional org_id to list one organization's schedules.
+func (s *Server) handleReportSchedules(w http.ResponseWriter, r *http.Request) {
+	mgr, ok := s.reportScheduler(w)
+	if !ok {
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		list, err := mgr.List(r.URL.Query().Get("org_id"))
+		if err != nil {
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		s.respondJSON(w, http.StatusOK, list)
+
+	case http.MethodPost:
+		var req reports.ScheduleRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		createdBy := ""
+		if user := s.getUserFromContext(r); user != nil {
+			createdBy = user.ID
+		}
+		sched, err := mgr.Create(req, createdBy)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		s.auditReportSchedule(r, "report_schedule.create", sched)
+		s.respondJSON(w, http.StatusCreated, sched)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// handleReportSchedule serves one schedule and its run log
+// GET    /api/v1/report-schedules/{id}      - Get a schedule
+// PUT    /api/v1/report-schedules/{id}      - Update a schedule
+// DELETE /api/v1/report-schedules/{id}      - Delete a schedule and its runs
+// POST   /api/v1/report-schedules/{id}/run  - Generate and deliver the report now
+// GET    /api/v1/report-schedules/{id}/runs - Page through the run log
+func (s *Server) handleReportSchedule(w http.ResponseWriter, r *http.Request) {
+	mgr, ok := s.reportScheduler(w)
+	if !ok {
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1/report-schedules/")
+	parts := strings.Split(strings.TrimSuffix(path, "/"), "/")
+	id := parts[0]
+	if id == "" {
+		s.respondError(w, http.StatusBadRequest, "Schedule ID required")
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		s.handleReportScheduleResource(w, r, mgr, id)
+
+	case len(parts) == 2 && parts[1] == "run":
+		if r.Method != http.MethodPost {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		run, err := mgr.RunNow(r.Context(), id)
+		if err != nil {
+			s.respondReportScheduleError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, run)
+
+	case len(parts) == 2 && parts[1] == "runs":
+		if r.Method != http.MethodGet {
+			s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		lq, err := parseListQuery(r, reportRunListOptions)
+		if err != nil {
+			s.respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		runs, total, err := mgr.Runs(reports.RunFilter{
+			ScheduleID: id,
+			Status:     r.URL.Query().Get("status"),
+			Ascending:  !lq.desc,
+			Limit:      lq.fetch(),
+			Offset:     lq.offset,
+			After:      lq.after,
+		})
+		if err != nil {
+			s.respondReportScheduleError(w, err)
+			return
+		}
+		page, p := pageOf(runs, lq, total, func(run *reports.Run) []interface{} { return []interface{}{run.StartedAt, run.ID} })
+		s.respondList(w, r, "runs", page, p, nil)
+
+	default:
+		s.respondError(w, http.StatusNotFound, "Not found")
+	}
+}
+
+// handleReportScheduleResource handles GET/PUT/DELETE of one schedule
+func (s *Server) handleReportScheduleResource(w http.ResponseWriter, r *http.Request, mgr *reports.Manager, id string) {
+	switch r.Method {
+	case http.MethodGet:
+		sched, err := mgr.Get(id)
+		if err != nil {
+			s.respondReportScheduleError(w, err)
+			return
+		}
+		s.respondJSON(w, http.StatusOK, sched)
+
+	case http.MethodPut:
+		var req reports.ScheduleRequest
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		sched, err := mgr.Update(id, req)
+		if err != nil {
+			s.respondReportScheduleError(w, err)
+			return
+		}
+		s.auditReportSchedule(r, "report_schedule.update", sched)
+		s.respondJSON(w, http.StatusOK, sched)
+
+	case http.MethodDelete:
+		sched, err := mgr.Get(id)
+		if err != nil {
+			s.respondReportScheduleError(w, err)
+			return
+		}
+		if err := mgr.Delete(id); err != nil {
+			s.respondReportScheduleError(w, err)
+			return
+		}
+		s.auditReportSchedule(r, "report_schedule.delete", sched)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+	}
+}
+
+// respondReportScheduleError maps report schedule errors to status codes
+func (s *Server) respondReportScheduleError(w http.ResponseWriter, err error) {
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "not found"):
+		s.respondError(w, http.StatusNotFound, msg)
+	case strings.Contains(msg, "already running"):
+		s.respondError(w, http.StatusConflict, msg)
+	case strings.Contains(msg, "must"), strings.Contains(msg, "invalid"):
+		s.respondError(w, http.StatusBadRequest, msg)
+	default:
+		s.respondError(w, http.StatusInternalServerError, msg)
+	}
+}
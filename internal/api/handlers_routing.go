@@ -17,6 +17,7 @@ func (s *Server) handleSelectProvider(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Policy       string                        `json:"policy"`       // minimize_cost, minimize_latency, maximize_quality, balanced
 		Requirements *routing.ProviderRequirements `json:"requirements"` // Optional requirements
+		ProjectID    string                        `json:"project_id"`   // Optional; applies the project's compliance constraints
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -29,6 +30,15 @@ func (s *Server) handleSelectProvider(w http.ResponseWriter, r *http.Request) {
 		req.Policy = "balanced"
 	}
 
+	if req.ProjectID != "" {
+		project, err := s.app.GetProjectManager().GetProject(req.ProjectID)
+		if err != nil {
+			http.Error(w, "Project not found", http.StatusNotFound)
+			return
+		}
+		req.Requirements = req.Requirements.WithCompliance(project.Compliance)
+	}
+
 	provider, err := s.app.SelectProvider(r.Context(), req.Requirements, req.Policy)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
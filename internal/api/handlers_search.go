@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/search"
+)
+
+// SearchResponse is the result of a search
+type SearchResponse struct {
+	Query   string           `json:"query"`
+	Results []*search.Result `json:"results"`
+	Count   int              `json:"count"`
+}
+
+// handleSearch handles GET /api/v1/search: activities and beads, with
+// their comments, matching every word of q, best matches first. Each word
+// also matches longer words it starts.
+// GET /api/v1/search?q=xxx&kind=activity|bead&project_id=xxx&actor_id=xxx&since=xxx&until=xxx&limit=20
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	mgr := s.app.GetSearch()
+	if mgr == nil {
+		s.respondError(w, http.StatusServiceUnavailable, "Search not available")
+		return
+	}
+
+	q := r.URL.Query()
+	text := q.Get("q")
+	query := search.Query{
+		Kind:      q.Get("kind"),
+		OrgID:     auth.GetOrgIDFromRequest(r),
+		ProjectID: q.Get("project_id"),
+		ActorID:   q.Get("actor_id"),
+	}
+	for name, dst := range map[string]*time.Time{"since": &query.Since, "until": &query.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				s.respondError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s: %v", name, err))
+				return
+			}
+			*dst = t
+		}
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			s.respondError(w, http.StatusBadRequest, "Invalid limit")
+			return
+		}
+		query.Limit = n
+	}
+	if len(search.Terms(text)) == 0 {
+		s.respondError(w, http.StatusBadRequest, "q must contain a word to search for")
+		return
+	}
+	if query.Kind != "" && query.Kind != search.KindActivity && query.Kind != search.KindBead {
+		s.respondError(w, http.StatusBadRequest, "Invalid kind (must be activity or bead)")
+		return
+	}
+
+	results, err := mgr.Search(text, query)
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to search: %v", err))
+		return
+	}
+	if results == nil {
+		results = []*search.Result{}
+	}
+	s.respondJSON(w, http.StatusOK, SearchResponse{Query: text, Results: results, Count: len(results)})
+}
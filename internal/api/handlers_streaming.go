@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -95,8 +96,9 @@ func (s *Server) handleStreamChatCompletion(w http.ResponseWriter, r *http.Reque
 		Stream:      true,
 	}
 
-	// Create context with timeout
-	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+	// Create context with timeout; the caller is waiting, so the request
+	// skips ahead of agent work on a saturated provider
+	ctx, cancel := context.WithTimeout(provider.WithPriority(r.Context(), provider.PriorityP0), 5*time.Minute)
 	defer cancel()
 
 	var streamedText strings.Builder
@@ -218,13 +220,19 @@ func (s *Server) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call provider directly (testing endpoint - skip health checks)
-	resp, err := registeredProvider.Protocol.CreateChatCompletion(r.Context(), providerReq)
+	resp, cached, err := s.cachedChatCompletion(provider.WithPriority(r.Context(), provider.PriorityP0), req.ProviderID, registeredProvider, providerReq)
+	if errors.Is(err, provider.ErrQueueFull) {
+		s.respondError(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
 	if err != nil {
 		s.respondError(w, http.StatusBadGateway, fmt.Sprintf("Provider error: %v", err))
 		return
 	}
 
-	if router := s.app.GetActionRouter(); router != nil {
+	// A cached response's actions already ran for the request that cached
+	// it, so they are not replayed against this caller's bead
+	if router := s.app.GetActionRouter(); router != nil && !cached {
 		raw := ""
 		if len(resp.Choices) > 0 {
 			raw = resp.Choices[0].Message.Content
@@ -246,6 +254,45 @@ func (s *Server) handleChatCompletion(w http.ResponseWriter, r *http.Request) {
 	s.respondJSON(w, http.StatusOK, resp)
 }
 
+// cachedChatCompletion answers a completion from the response cache when a
+// logically identical request was answered before, and caches the
+// provider's answer otherwise, reporting whether the answer came from the
+// cache. Requests are keyed by Cache.Key, so the configured
+// canonicalization decides what counts as identical.
+func (s *Server) cachedChatCompletion(ctx context.Context, providerID string, protocol provider.Protocol, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, bool, error) {
+	if s.cache == nil {
+		resp, err := protocol.CreateChatCompletion(ctx, req)
+		return resp, false, err
+	}
+	key, err := s.cache.Key(providerID, req.Model, req)
+	if err != nil {
+		resp, err := protocol.CreateChatCompletion(ctx, req)
+		return resp, false, err
+	}
+	if entry, ok := s.cache.Get(ctx, key); ok {
+		// Redis entries come back as generic JSON values
+		var cached provider.ChatCompletionResponse
+		if data, err := json.Marshal(entry.Response); err == nil && json.Unmarshal(data, &cached) == nil {
+			s.recordCacheLookup(true)
+			return &cached, true, nil
+		}
+	}
+	s.recordCacheLookup(false)
+
+	resp, err := protocol.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, false, err
+	}
+	_ = s.cache.Set(ctx, key, resp, 0, map[string]interface{}{
+		"provider_id":       providerID,
+		"model_name":        req.Model,
+		"total_tokens":      int64(resp.Usage.TotalTokens),
+		"prompt_tokens":     int64(resp.Usage.PromptTokens),
+		"completion_tokens": int64(resp.Usage.CompletionTokens),
+	})
+	return resp, false, nil
+}
+
 func appendActionPrompt(messages []provider.ChatMessage) []provider.ChatMessage {
 	prompt := strings.TrimSpace(actions.ActionPrompt)
 	if prompt == "" {
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/cache"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// countingProvider counts the completions that reach the provider
+type countingProvider struct {
+	*provider.MockProvider
+	calls int
+}
+
+func (p *countingProvider) CreateChatCompletion(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	p.calls++
+	return p.MockProvider.CreateChatCompletion(ctx, req)
+}
+
+func TestCachedChatCompletion_CanonicalRequestsHit(t *testing.T) {
+	s := &Server{cache: cache.New(&cache.Config{
+		Enabled:    true,
+		DefaultTTL: time.Hour,
+		MaxSize:    100,
+		Keys:       cache.KeyConfig{Canonicalize: true, IgnoreTemperatureBelow: 0.2},
+	})}
+	p := &countingProvider{MockProvider: provider.NewMockProvider()}
+	ctx := context.Background()
+
+	first, hit, err := s.cachedChatCompletion(ctx, "mock", p, &provider.ChatCompletionRequest{
+		Model:    "m",
+		Messages: []provider.ChatMessage{{Role: "user", Content: "Summarize the bead"}},
+	})
+	if err != nil {
+		t.Fatalf("cachedChatCompletion failed: %v", err)
+	}
+	if hit {
+		t.Error("expected the first request to miss")
+	}
+	// Same request up to role case, trailing whitespace and a near-zero
+	// temperature
+	second, hit, err := s.cachedChatCompletion(ctx, "mock", p, &provider.ChatCompletionRequest{
+		Model:       "m",
+		Messages:    []provider.ChatMessage{{Role: "User", Content: "Summarize the bead  \n"}},
+		Temperature: 0.1,
+	})
+	if err != nil {
+		t.Fatalf("cachedChatCompletion failed: %v", err)
+	}
+	if p.calls != 1 || !hit {
+		t.Errorf("expected the second request to be served from the cache, provider called %d times", p.calls)
+	}
+	if second.Choices[0].Message.Content != first.Choices[0].Message.Content {
+		t.Errorf("expected the cached response, got %q", second.Choices[0].Message.Content)
+	}
+
+	if _, _, err := s.cachedChatCompletion(ctx, "mock", p, &provider.ChatCompletionRequest{
+		Model:    "m",
+		Messages: []provider.ChatMessage{{Role: "user", Content: "Summarize the project"}},
+	}); err != nil {
+		t.Fatalf("cachedChatCompletion failed: %v", err)
+	}
+	if p.calls != 2 {
+		t.Errorf("expected a different prompt to miss, provider called %d times", p.calls)
+	}
+}
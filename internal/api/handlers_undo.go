@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// handleBeadUndo reverts everything a bead did in its project's repository
+// POST /api/v1/beads/{id}/undo
+func (s *Server) handleBeadUndo(w http.ResponseWriter, r *http.Request, beadID string) {
+	if r.Method != http.MethodPost {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	var req models.UndoBeadRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSON(r, &req); err != nil {
+			s.respondError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	result, err := s.app.UndoBead(r.Context(), beadID, s.reviewerID(r), req.Reason)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "bead not found"):
+			s.respondError(w, http.StatusNotFound, err.Error())
+		case strings.Contains(err.Error(), "in progress"):
+			s.respondError(w, http.StatusConflict, err.Error())
+		case strings.Contains(err.Error(), "not configured"):
+			s.respondError(w, http.StatusServiceUnavailable, err.Error())
+		default:
+			s.respondError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	s.respondJSON(w, http.StatusOK, result)
+}
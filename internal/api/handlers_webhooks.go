@@ -155,6 +155,12 @@ func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Check results feed CI tracking for bead branches
+	if eventType == "check_run" || eventType == "status" {
+		s.handleGitHubCIEvent(w, r, eventType, body)
+		return
+	}
+
 	// Parse the payload
 	var payload GitHubWebhookPayload
 	if err := json.Unmarshal(body, &payload); err != nil {
@@ -162,6 +168,9 @@ func (s *Server) handleGitHubWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Issues and their comments feed the beads imported from them
+	s.syncGitHubIssue(r, eventType, &payload)
+
 	// Process the event
 	webhookEvent := s.processGitHubEvent(eventType, &payload)
 	if webhookEvent == nil {
@@ -432,3 +432,58 @@ func TestWebhookIntegration(t *testing.T) {
 		t.Errorf("Integration test failed with status %d: %s", w.Code, w.Body.String())
 	}
 }
+
+func TestGitLabWebhook_Token(t *testing.T) {
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			WebhookSecret: "test-secret",
+		},
+	}
+	server := NewServer(nil, nil, nil, cfg)
+
+	send := func(token, event string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/gitlab", bytes.NewReader([]byte(`{"object_attributes":{"ref":"main","status":"success"}}`)))
+		req.Header.Set("X-Gitlab-Token", token)
+		req.Header.Set("X-Gitlab-Event", event)
+		w := httptest.NewRecorder()
+		server.handleGitLabWebhook(w, req)
+		return w.Code
+	}
+
+	if code := send("wrong", "Pipeline Hook"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401 for a wrong token, got %d", code)
+	}
+	if code := send("test-secret", "Push Hook"); code != http.StatusOK {
+		t.Errorf("Expected status 200 for an ignored event, got %d", code)
+	}
+	if code := send("test-secret", "Pipeline Hook"); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+}
+
+func TestJiraWebhook_Signature(t *testing.T) {
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			WebhookSecret: "test-secret",
+		},
+	}
+	server := NewServer(nil, nil, nil, cfg)
+	body := []byte(`{"webhookEvent": "jira:issue_updated", "issue": {"key": "OPS-7", "fields": {"summary": "Disk full"}}}`)
+
+	send := func(signature string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/jira", bytes.NewReader(body))
+		req.Header.Set("X-Hub-Signature", signature)
+		w := httptest.NewRecorder()
+		server.handleJiraWebhook(w, req)
+		return w.Code
+	}
+
+	if code := send("sha256=invalid"); code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", code)
+	}
+	mac := hmac.New(sha256.New, []byte("test-secret"))
+	mac.Write(body)
+	if code := send("sha256=" + hex.EncodeToString(mac.Sum(nil))); code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", code)
+	}
+}
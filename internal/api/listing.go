@@ -0,0 +1,276 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxListLimit caps the page size a client may ask for
+const maxListLimit = 1000
+
+// listOptions describes how a list endpoint pages and sorts
+type listOptions struct {
+	defaultLimit int        // 0 returns the whole list unless the client asks for a page
+	sorts        []listSort // fields the list can be sorted by; the first is the default
+	defaultDesc  bool       // default direction of the default sort
+}
+
+// listSort is a field a list can be sorted by. key spells out the kinds of
+// the values in the list's keyset for that order, one letter per value:
+// t for a time, i for an integer and s for a string. "ts" is a timestamp
+// followed by an ID.
+type listSort struct {
+	field string
+	key   string
+}
+
+// listQuery is the page and order a client asked for:
+//
+//	?limit=50&cursor=<next_cursor>&sort=-created_at
+//
+// sort takes a field, prefixed with "-" for descending order (or use
+// order=asc|desc). cursor is opaque: it holds the keyset (sort values and
+// ID) of the last item of the previous page, so each page continues where
+// the last one stopped however the list changed in between. offset is
+// accepted for older clients.
+type listQuery struct {
+	limit  int // 0 means no limit
+	offset int
+	sort   string
+	desc   bool
+	after  []interface{} // keyset from the cursor; nil on the first page
+}
+
+// parseListQuery reads the pagination and sort parameters of r
+func parseListQuery(r *http.Request, opts listOptions) (listQuery, error) {
+	q := r.URL.Query()
+	lq := listQuery{limit: opts.defaultLimit}
+	var key string
+	if len(opts.sorts) > 0 {
+		lq.sort = opts.sorts[0].field
+		key = opts.sorts[0].key
+		lq.desc = opts.defaultDesc
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return lq, fmt.Errorf("limit must be a positive integer")
+		}
+		lq.limit = n
+	}
+	if lq.limit > maxListLimit {
+		lq.limit = maxListLimit
+	}
+
+	if v := q.Get("sort"); v != "" {
+		field := strings.TrimPrefix(v, "-")
+		i := slices.IndexFunc(opts.sorts, func(s listSort) bool { return s.field == field })
+		if i < 0 {
+			fields := make([]string, len(opts.sorts))
+			for j, s := range opts.sorts {
+				fields[j] = s.field
+			}
+			return lq, fmt.Errorf("cannot sort by %q; supported: %s", field, strings.Join(fields, ", "))
+		}
+		lq.sort = field
+		key = opts.sorts[i].key
+		lq.desc = strings.HasPrefix(v, "-")
+	}
+	switch strings.ToLower(q.Get("order")) {
+	case "":
+	case "asc":
+		lq.desc = false
+	case "desc":
+		lq.desc = true
+	default:
+		return lq, fmt.Errorf("order must be asc or desc")
+	}
+
+	switch {
+	case q.Get("cursor") != "":
+		after, err := decodeCursor(q.Get("cursor"), lq, key)
+		if err != nil {
+			return lq, err
+		}
+		lq.after = after
+	case q.Get("offset") != "":
+		n, err := strconv.Atoi(q.Get("offset"))
+		if err != nil || n < 0 {
+			return lq, fmt.Errorf("offset must be a non-negative integer")
+		}
+		lq.offset = n
+	}
+	// A cursor past the first page implies a page size
+	if (lq.after != nil || lq.offset > 0) && lq.limit == 0 {
+		lq.limit = maxListLimit
+	}
+	return lq, nil
+}
+
+// fetch returns how many items to ask the store for: one more than the
+// page holds, so pageOf can tell whether another page follows
+func (q listQuery) fetch() int {
+	if q.limit == 0 {
+		return 0
+	}
+	return q.limit + 1
+}
+
+// listPage describes the page of a list being returned
+type listPage struct {
+	query listQuery
+	count int    // items on this page
+	total int    // items in the whole filtered list
+	next  string // cursor of the following page; "" on the last
+}
+
+// pageOf trims the extra item fetched by q.fetch from items and, when
+// there was one, makes the next cursor from the key of the last item kept
+func pageOf[T any](items []T, q listQuery, total int, key func(T) []interface{}) ([]T, listPage) {
+	p := listPage{query: q, total: total}
+	if q.limit > 0 && len(items) > q.limit {
+		items = items[:q.limit]
+		p.next = encodeCursor(q, key(items[len(items)-1]))
+	}
+	p.count = len(items)
+	return items, p
+}
+
+// meta returns the paging fields added to enveloped list responses
+func (p listPage) meta() map[string]interface{} {
+	meta := map[string]interface{}{
+		"count": p.count,
+		"total": p.total,
+		"limit": p.query.limit,
+	}
+	if p.query.offset > 0 {
+		meta["offset"] = p.query.offset
+	}
+	if p.next != "" {
+		meta["next_cursor"] = p.next
+	}
+	return meta
+}
+
+// setPageHeaders sets X-Total-Count and an RFC 8288 Link header pointing to
+// the first and next pages
+func setPageHeaders(w http.ResponseWriter, r *http.Request, p listPage) {
+	w.Header().Set("X-Total-Count", strconv.Itoa(p.total))
+
+	var links []string
+	link := func(rel, cursor string) {
+		q := r.URL.Query()
+		q.Del("offset")
+		q.Del("cursor")
+		if cursor != "" {
+			q.Set("cursor", cursor)
+		}
+		if p.query.limit > 0 {
+			q.Set("limit", strconv.Itoa(p.query.limit))
+		}
+		u := url.URL{Path: r.URL.Path, RawQuery: q.Encode()}
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", u.String(), rel))
+	}
+	if p.query.after != nil || p.query.offset > 0 {
+		link("first", "")
+	}
+	if p.next != "" {
+		link("next", p.next)
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+// respondList writes one page of a list. With a key, items are wrapped in
+// an object alongside the page's metadata (and any extra fields); without
+// one they are written as a bare array, for endpoints that have always
+// returned arrays.
+func (s *Server) respondList(w http.ResponseWriter, r *http.Request, key string, items interface{}, p listPage, extra map[string]interface{}) {
+	setPageHeaders(w, r, p)
+	if key == "" {
+		s.respondJSON(w, http.StatusOK, items)
+		return
+	}
+	body := p.meta()
+	for k, v := range extra {
+		body[k] = v
+	}
+	body[key] = items
+	s.respondJSON(w, http.StatusOK, body)
+}
+
+// listCursor is the decoded form of a cursor: the order it was made for
+// and the keyset of the last item of its page. Values are tagged with
+// their kind ("t:", "i:" or "s:") so they decode to the types the stores
+// compare with.
+type listCursor struct {
+	Sort string   `json:"s"`
+	Desc bool     `json:"d,omitempty"`
+	Key  []string `json:"k"`
+}
+
+func encodeCursor(q listQuery, key []interface{}) string {
+	c := listCursor{Sort: q.sort, Desc: q.desc, Key: make([]string, len(key))}
+	for i, v := range key {
+		switch v := v.(type) {
+		case time.Time:
+			c.Key[i] = "t:" + v.Format(time.RFC3339Nano)
+		case int:
+			c.Key[i] = "i:" + strconv.Itoa(v)
+		default:
+			c.Key[i] = "s:" + fmt.Sprint(v)
+		}
+	}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns the keyset of a cursor made for the order of q,
+// whose values must be of the kinds listed in key
+func decodeCursor(cursor string, q listQuery, key string) ([]interface{}, error) {
+	invalid := fmt.Errorf("invalid cursor")
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var c listCursor
+	if err := json.Unmarshal(raw, &c); err != nil || len(c.Key) != len(key) {
+		return nil, invalid
+	}
+	if c.Sort != q.sort || c.Desc != q.desc {
+		return nil, fmt.Errorf("cursor was made for another sort order")
+	}
+	after := make([]interface{}, len(c.Key))
+	for i, v := range c.Key {
+		kind, value, ok := strings.Cut(v, ":")
+		if !ok || kind != key[i:i+1] {
+			return nil, invalid
+		}
+		switch kind {
+		case "t":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, invalid
+			}
+			after[i] = t
+		case "i":
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, invalid
+			}
+			after[i] = n
+		default:
+			after[i] = value
+		}
+	}
+	return after, nil
+}
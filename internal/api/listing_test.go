@@ -0,0 +1,192 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/logging"
+)
+
+var testListOptions = listOptions{defaultLimit: 10, sorts: []listSort{{"name", "ss"}, {"size", "is"}}}
+
+func TestParseListQuery_Defaults(t *testing.T) {
+	lq, err := parseListQuery(httptest.NewRequest(http.MethodGet, "/items", nil), testListOptions)
+	if err != nil {
+		t.Fatalf("parseListQuery failed: %v", err)
+	}
+	if lq.limit != 10 || lq.offset != 0 || lq.sort != "name" || lq.desc || lq.after != nil {
+		t.Errorf("unexpected defaults: %+v", lq)
+	}
+}
+
+func TestParseListQuery_Params(t *testing.T) {
+	cases := []struct {
+		query string
+		want  listQuery
+	}{
+		{"limit=5&sort=-size", listQuery{limit: 5, sort: "size", desc: true}},
+		{"sort=size&order=desc", listQuery{limit: 10, sort: "size", desc: true}},
+		{"offset=7", listQuery{limit: 10, offset: 7, sort: "name"}},
+		{"limit=5000", listQuery{limit: maxListLimit, sort: "name"}},
+	}
+	for _, tc := range cases {
+		got, err := parseListQuery(httptest.NewRequest(http.MethodGet, "/items?"+tc.query, nil), testListOptions)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestParseListQuery_Cursor(t *testing.T) {
+	cursor := encodeCursor(listQuery{sort: "size", desc: true}, []interface{}{42, "item-7"})
+	lq, err := parseListQuery(httptest.NewRequest(http.MethodGet, "/items?sort=-size&cursor="+cursor, nil), testListOptions)
+	if err != nil {
+		t.Fatalf("parseListQuery failed: %v", err)
+	}
+	if want := []interface{}{42, "item-7"}; !reflect.DeepEqual(lq.after, want) {
+		t.Errorf("after = %#v, want %#v", lq.after, want)
+	}
+
+	// The cursor only continues the order it was made for
+	if _, err := parseListQuery(httptest.NewRequest(http.MethodGet, "/items?sort=size&cursor="+cursor, nil), testListOptions); err == nil {
+		t.Error("expected an error for a cursor of another sort order")
+	}
+	// and its values must have the kinds of that order's keyset
+	bad := encodeCursor(listQuery{sort: "size", desc: true}, []interface{}{"big", "item-7"})
+	if _, err := parseListQuery(httptest.NewRequest(http.MethodGet, "/items?sort=-size&cursor="+bad, nil), testListOptions); err == nil {
+		t.Error("expected an error for a cursor with a string where an integer belongs")
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	ts := time.Date(2026, 3, 4, 5, 6, 7, 890, time.FixedZone("", 3600))
+	q := listQuery{sort: "created_at"}
+	after, err := decodeCursor(encodeCursor(q, []interface{}{ts, "b-1"}), q, "ts")
+	if err != nil {
+		t.Fatalf("decodeCursor failed: %v", err)
+	}
+	if got := after[0].(time.Time); !got.Equal(ts) || got.Format(time.RFC3339Nano) != ts.Format(time.RFC3339Nano) {
+		t.Errorf("timestamp came back as %v, want %v", got, ts)
+	}
+	if after[1] != "b-1" {
+		t.Errorf("id came back as %v", after[1])
+	}
+}
+
+func TestParseListQuery_Invalid(t *testing.T) {
+	for _, query := range []string{"limit=0", "limit=abc", "cursor=bogus", "offset=-1", "sort=color", "order=sideways"} {
+		if _, err := parseListQuery(httptest.NewRequest(http.MethodGet, "/items?"+query, nil), testListOptions); err == nil {
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+}
+
+func TestPageOf(t *testing.T) {
+	q := listQuery{limit: 2, sort: "size"}
+	key := func(n int) []interface{} { return []interface{}{n, strconv.Itoa(n)} }
+
+	// One more item than the page holds means another page follows
+	page, p := pageOf([]int{1, 3, 5}, q, 5, key)
+	if fmt.Sprint(page) != "[1 3]" || p.count != 2 || p.total != 5 {
+		t.Errorf("unexpected page %v (%+v)", page, p)
+	}
+	after, err := decodeCursor(p.next, q, "is")
+	if err != nil || !reflect.DeepEqual(after, []interface{}{3, "3"}) {
+		t.Errorf("next cursor should continue after 3, got %v (%v)", after, err)
+	}
+
+	page, p = pageOf([]int{7}, q, 5, key)
+	if len(page) != 1 || p.next != "" {
+		t.Errorf("last page should have one item and no next cursor, got %v", page)
+	}
+}
+
+func TestSetPageHeaders(t *testing.T) {
+	q := listQuery{limit: 10, sort: "created_at", after: []interface{}{"x"}}
+	next := encodeCursor(q, []interface{}{"y"})
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/beads?status=open&cursor=abc&limit=10", nil)
+	w := httptest.NewRecorder()
+	setPageHeaders(w, r, listPage{query: q, count: 10, total: 35, next: next})
+
+	if got := w.Header().Get("X-Total-Count"); got != "35" {
+		t.Errorf("X-Total-Count = %q, want 35", got)
+	}
+	link := w.Header().Get("Link")
+	for _, want := range []string{
+		`</api/v1/beads?limit=10&status=open>; rel="first"`,
+		`</api/v1/beads?cursor=` + next + `&limit=10&status=open>; rel="next"`,
+	} {
+		if !strings.Contains(link, want) {
+			t.Errorf("Link header missing %s\ngot: %s", want, link)
+		}
+	}
+}
+
+func TestHandleLogsRecent_Paginates(t *testing.T) {
+	s := newTestServer()
+	s.logManager = logging.NewManager(nil)
+	for i := 0; i < 5; i++ {
+		s.logManager.Info("test", fmt.Sprintf("entry %d", i), nil)
+	}
+
+	w := httptest.NewRecorder()
+	s.HandleLogsRecent(w, httptest.NewRequest(http.MethodGet, "/api/v1/logs/recent?limit=2", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp struct {
+		Logs       []logging.LogEntry `json:"logs"`
+		Count      int                `json:"count"`
+		Total      int                `json:"total"`
+		NextCursor string             `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Count != 2 || resp.Total != 5 || resp.NextCursor == "" {
+		t.Errorf("unexpected page: count=%d total=%d next=%q", resp.Count, resp.Total, resp.NextCursor)
+	}
+	if w.Header().Get("X-Total-Count") != "5" || !strings.Contains(w.Header().Get("Link"), `rel="next"`) {
+		t.Errorf("missing paging headers: %v", w.Header())
+	}
+
+	// Following the cursors visits every entry once
+	seen := map[string]bool{}
+	for page := 1; ; page++ {
+		for _, e := range resp.Logs {
+			if seen[e.ID] {
+				t.Errorf("entry %s returned twice", e.ID)
+			}
+			seen[e.ID] = true
+		}
+		if resp.NextCursor == "" || page > 5 {
+			break
+		}
+		w = httptest.NewRecorder()
+		s.HandleLogsRecent(w, httptest.NewRequest(http.MethodGet, "/api/v1/logs/recent?limit=2&cursor="+resp.NextCursor, nil))
+		resp.Logs, resp.NextCursor = nil, ""
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("paging visited %d entries, want 5", len(seen))
+	}
+
+	w = httptest.NewRecorder()
+	s.HandleLogsRecent(w, httptest.NewRequest(http.MethodGet, "/api/v1/logs/recent?sort=level", nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unsupported sort, got %d", w.Code)
+	}
+}
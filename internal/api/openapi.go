@@ -0,0 +1,531 @@
+package api
+
+//go:generate go run ../../cmd/loom-apigen -spec ../../api/openapi.json -yaml ../../api/openapi.yaml -client ../../pkg/client/zz_generated.go
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/apispec"
+	"github.com/jordanhubbard/loom/internal/artifacts"
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/backup"
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/chat"
+	"github.com/jordanhubbard/loom/internal/cistatus"
+	"github.com/jordanhubbard/loom/internal/costestimate"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/demo"
+	"github.com/jordanhubbard/loom/internal/depupdates"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/evaluation"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/issuesync"
+	"github.com/jordanhubbard/loom/internal/knowledge"
+	"github.com/jordanhubbard/loom/internal/logging"
+	loompkg "github.com/jordanhubbard/loom/internal/loom"
+	"github.com/jordanhubbard/loom/internal/lsp"
+	"github.com/jordanhubbard/loom/internal/memory"
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/onboarding"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/internal/plugin"
+	"github.com/jordanhubbard/loom/internal/policy"
+	"github.com/jordanhubbard/loom/internal/pricing"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/prreview"
+	"github.com/jordanhubbard/loom/internal/reports"
+	"github.com/jordanhubbard/loom/internal/verification"
+	"github.com/jordanhubbard/loom/pkg/models"
+	pkgplugin "github.com/jordanhubbard/loom/pkg/plugin"
+)
+
+// apiInfo describes the API in the OpenAPI document
+var apiInfo = apispec.Info{
+	Title:   "Loom API",
+	Version: "1.0.0",
+	Description: "Programmatic access to the Loom agent orchestration system. " +
+		"Authenticate with a JWT from /api/v1/auth/login or an API key, sent as a bearer token or in the X-API-Key header.",
+	Error: models.ErrorResponse{},
+}
+
+// apiOperations is the documented part of the route table in SetupRoutes.
+// The OpenAPI document and the Go client in pkg/client are generated from
+// it; run `go generate ./internal/api` after changing it.
+var apiOperations = []apispec.Operation{
+	{ID: "Health", Method: http.MethodGet, Path: "/api/v1/health", Tag: "system", Summary: "Reports whether the API is up",
+		Response: map[string]string{}, Public: true},
+
+	{ID: "Login", Method: http.MethodPost, Path: "/api/v1/auth/login", Tag: "auth", Summary: "Exchanges a username and password for a JWT",
+		Request: auth.LoginRequest{}, Response: auth.LoginResponse{}, Public: true},
+	{ID: "GetCurrentUser", Method: http.MethodGet, Path: "/api/v1/auth/me", Tag: "auth", Summary: "Returns the authenticated user",
+		Response: auth.User{}},
+
+	{ID: "ListOrgs", Method: http.MethodGet, Path: "/api/v1/orgs", Tag: "orgs", Summary: "Lists organizations; members of an organization other than the default see only their own",
+		Response: []org.Organization{}},
+	{ID: "CreateOrg", Method: http.MethodPost, Path: "/api/v1/orgs", Tag: "orgs", Summary: "Creates an organization",
+		Request: org.Request{}, Response: org.Organization{}, Status: http.StatusCreated},
+	{ID: "GetOrg", Method: http.MethodGet, Path: "/api/v1/orgs/{id}", Tag: "orgs", Summary: "Returns an organization",
+		Response: org.Organization{}},
+	{ID: "UpdateOrg", Method: http.MethodPut, Path: "/api/v1/orgs/{id}", Tag: "orgs", Summary: "Renames an organization or changes its daily budget",
+		Request: org.Request{}, Response: org.Organization{}},
+	{ID: "DeleteOrg", Method: http.MethodDelete, Path: "/api/v1/orgs/{id}", Tag: "orgs", Summary: "Deletes an organization that owns no projects or users"},
+	{ID: "ListSharedLessons", Method: http.MethodGet, Path: "/api/v1/orgs/{id}/lessons", Tag: "orgs", Summary: "Lists the organization's shared lessons, newest first",
+		Query: []apispec.Param{
+			{Name: "status", Description: "proposed or shared; both by default"},
+			{Name: "limit", Description: "Most lessons to return, 50 by default"},
+		},
+		Response: []models.Lesson{}},
+	{ID: "CreateSharedLesson", Method: http.MethodPost, Path: "/api/v1/orgs/{id}/lessons", Tag: "orgs", Summary: "Adds a lesson to the prompts of all the organization's projects that have not opted out",
+		Request: models.SharedLessonRequest{}, Response: models.Lesson{}, Status: http.StatusCreated},
+	{ID: "ApproveSharedLesson", Method: http.MethodPost, Path: "/api/v1/orgs/{id}/lessons/{lesson_id}/approve", Tag: "orgs", Summary: "Approves a lesson promoted from a project, putting it into the prompts of the organization's projects",
+		Response: models.Lesson{}},
+	{ID: "DeleteSharedLesson", Method: http.MethodDelete, Path: "/api/v1/orgs/{id}/lessons/{lesson_id}", Tag: "orgs", Summary: "Rejects a promoted lesson or retires a shared one; the project lesson it came from is kept"},
+
+	{ID: "ListPersonas", Method: http.MethodGet, Path: "/api/v1/personas", Tag: "personas", Summary: "Lists agent personas",
+		Response: []models.Persona{}},
+	{ID: "CreatePersona", Method: http.MethodPost, Path: "/api/v1/personas", Tag: "personas", Summary: "Creates a persona from a first definition version",
+		Request: PersonaRequest{}, Response: persona.Version{}, Status: http.StatusCreated},
+	{ID: "GetPersona", Method: http.MethodGet, Path: "/api/v1/personas/{name}", Tag: "personas", Summary: "Returns a persona with its active definition",
+		Response: models.Persona{}},
+	{ID: "PublishPersona", Method: http.MethodPut, Path: "/api/v1/personas/{name}", Tag: "personas", Summary: "Publishes a new version of a persona's definition",
+		Request: PersonaRequest{}, Response: persona.Version{}},
+	{ID: "DeletePersona", Method: http.MethodDelete, Path: "/api/v1/personas/{name}", Tag: "personas", Summary: "Removes a persona's published definition versions"},
+	{ID: "ListPersonaVersions", Method: http.MethodGet, Path: "/api/v1/personas/{name}/versions", Tag: "personas", Summary: "Lists a persona's definition versions, newest first",
+		Response: []persona.Version{}},
+	{ID: "GetPersonaVersion", Method: http.MethodGet, Path: "/api/v1/personas/{name}/versions/{version}", Tag: "personas", Summary: "Returns one definition version of a persona",
+		Response: persona.Version{}},
+	{ID: "RollbackPersona", Method: http.MethodPost, Path: "/api/v1/personas/{name}/rollback", Tag: "personas", Summary: "Republishes an older definition version of a persona",
+		Request: PersonaRollbackRequest{}, Response: persona.Version{}},
+
+	{ID: "ListAgents", Method: http.MethodGet, Path: "/api/v1/agents", Tag: "agents", Summary: "Lists agents",
+		Response: []models.Agent{}},
+	{ID: "SpawnAgent", Method: http.MethodPost, Path: "/api/v1/agents", Tag: "agents", Summary: "Spawns an agent from a persona",
+		Request: models.SpawnAgentRequest{}, Response: models.Agent{}, Status: http.StatusCreated},
+	{ID: "GetAgent", Method: http.MethodGet, Path: "/api/v1/agents/{id}", Tag: "agents", Summary: "Returns an agent",
+		Response: models.Agent{}},
+	{ID: "StopAgent", Method: http.MethodDelete, Path: "/api/v1/agents/{id}", Tag: "agents", Summary: "Stops and removes an agent"},
+
+	{ID: "ListProjects", Method: http.MethodGet, Path: "/api/v1/projects", Tag: "projects", Summary: "Lists projects",
+		Response: []models.Project{}},
+	{ID: "CreateProject", Method: http.MethodPost, Path: "/api/v1/projects", Tag: "projects", Summary: "Creates a project",
+		Request: models.CreateProjectRequest{}, Response: models.Project{}, Status: http.StatusCreated},
+	{ID: "GetProject", Method: http.MethodGet, Path: "/api/v1/projects/{id}", Tag: "projects", Summary: "Returns a project",
+		Response: models.Project{}},
+	{ID: "UpdateProject", Method: http.MethodPut, Path: "/api/v1/projects/{id}", Tag: "projects", Summary: "Updates a project",
+		Request: models.UpdateProjectRequest{}, Response: models.Project{}},
+	{ID: "DeleteProject", Method: http.MethodDelete, Path: "/api/v1/projects/{id}", Tag: "projects", Summary: "Deletes a project"},
+	{ID: "GetProjectPolicy", Method: http.MethodGet, Path: "/api/v1/projects/{id}/policy", Tag: "projects", Summary: "Returns a project's active policy version and the global policy",
+		Response: ProjectPolicy{}},
+	{ID: "PublishProjectPolicy", Method: http.MethodPut, Path: "/api/v1/projects/{id}/policy", Tag: "projects", Summary: "Publishes a new version of a project's policy",
+		Request: PolicyRequest{}, Response: policy.Version{}},
+	{ID: "ListProjectPolicyVersions", Method: http.MethodGet, Path: "/api/v1/projects/{id}/policy/versions", Tag: "projects", Summary: "Lists a project's policy versions, newest first",
+		Response: []policy.Version{}},
+	{ID: "GetProjectPolicyVersion", Method: http.MethodGet, Path: "/api/v1/projects/{id}/policy/versions/{version}", Tag: "projects", Summary: "Returns one of a project's policy versions",
+		Response: policy.Version{}},
+	{ID: "EvaluateProjectPolicy", Method: http.MethodPost, Path: "/api/v1/projects/{id}/policy/evaluate", Tag: "projects", Summary: "Dry-runs a policy decision, optionally against a draft policy",
+		Request: PolicyEvaluateRequest{}, Response: policy.Decision{}},
+
+	{ID: "ListProjectTemplates", Method: http.MethodGet, Path: "/api/v1/project-templates", Tag: "project-templates", Summary: "Lists the shared project templates and those of the caller's organization",
+		Response: []projecttemplates.Template{}},
+	{ID: "CreateProjectTemplate", Method: http.MethodPost, Path: "/api/v1/project-templates", Tag: "project-templates", Summary: "Creates a project template",
+		Request: ProjectTemplateRequest{}, Response: projecttemplates.Template{}, Status: http.StatusCreated},
+	{ID: "GetProjectTemplate", Method: http.MethodGet, Path: "/api/v1/project-templates/{id}", Tag: "project-templates", Summary: "Returns a project template",
+		Response: projecttemplates.Template{}},
+	{ID: "UpdateProjectTemplate", Method: http.MethodPut, Path: "/api/v1/project-templates/{id}", Tag: "project-templates", Summary: "Updates the given fields of a project template",
+		Request: projecttemplates.Request{}, Response: projecttemplates.Template{}},
+	{ID: "DeleteProjectTemplate", Method: http.MethodDelete, Path: "/api/v1/project-templates/{id}", Tag: "project-templates", Summary: "Deletes a project template"},
+	{ID: "InstantiateProjectTemplate", Method: http.MethodPost, Path: "/api/v1/project-templates/{id}/instantiate", Tag: "project-templates",
+		Summary: "Makes a project from a template: registers it, generates its SSH key, clones it, staffs personas, assigns providers, files seed beads and subscribes webhooks; dry_run only reports issues",
+		Request: projecttemplates.InstantiateRequest{}, Response: projecttemplates.Result{}, Status: http.StatusCreated},
+
+	{ID: "ListBeads", Method: http.MethodGet, Path: "/api/v1/beads", Tag: "beads", Summary: "Lists beads",
+		Query: []apispec.Param{
+			{Name: "project_id", Description: "Only beads of this project"},
+			{Name: "status", Description: "Only beads with this status"},
+			{Name: "type", Description: "Only beads of this type"},
+			{Name: "assigned_to", Description: "Comma-separated agent IDs"},
+			{Name: "sort", Description: "priority, created_at, updated_at, title or status; prefix with - for descending"},
+			{Name: "limit", Description: "Page size; all matching beads when neither limit nor cursor is set"},
+			{Name: "cursor", Description: "Page to return, from the Link header of the previous page"},
+		},
+		Response: []models.Bead{}},
+	{ID: "CreateBead", Method: http.MethodPost, Path: "/api/v1/beads", Tag: "beads", Summary: "Files a bead",
+		Request: models.CreateBeadRequest{}, Response: models.Bead{}, Status: http.StatusCreated},
+	{ID: "GetBead", Method: http.MethodGet, Path: "/api/v1/beads/{id}", Tag: "beads", Summary: "Returns a bead",
+		Response: models.Bead{}},
+	{ID: "UpdateBead", Method: http.MethodPatch, Path: "/api/v1/beads/{id}", Tag: "beads", Summary: "Updates the given fields of a bead",
+		Request: models.UpdateBeadRequest{}, Response: models.Bead{}},
+	{ID: "ClaimBead", Method: http.MethodPost, Path: "/api/v1/beads/{id}/claim", Tag: "beads", Summary: "Assigns a bead to an agent",
+		Request: models.ClaimBeadRequest{}, Response: models.StatusResponse{}},
+	{ID: "VerifyBead", Method: http.MethodPost, Path: "/api/v1/beads/{id}/verify", Tag: "beads", Summary: "Runs the project's tests for a bead and attaches the result, filing a follow-up bead on failure",
+		Response: verification.Result{}},
+	{ID: "GetBeadCI", Method: http.MethodGet, Path: "/api/v1/beads/{id}/ci", Tag: "beads", Summary: "Returns the CI check status of a bead's branches, most recently updated first",
+		Response: []cistatus.Status{}},
+	{ID: "GetBeadCost", Method: http.MethodGet, Path: "/api/v1/beads/{id}/cost", Tag: "beads", Summary: "Returns the LLM cost, tokens and iterations spent on a bead since it was created, with its commits and what it had cost when each pull request opened",
+		Response: analytics.BeadCost{}},
+	{ID: "GetBeadPlan", Method: http.MethodGet, Path: "/api/v1/beads/{id}/plan", Tag: "beads", Summary: "Returns the execution plan an agent proposed for a bead in plan mode",
+		Response: models.ExecutionPlan{}},
+	{ID: "ApproveBeadPlan", Method: http.MethodPost, Path: "/api/v1/beads/{id}/plan/approve", Tag: "beads", Summary: "Approves a bead's proposed plan so the bead is dispatched to do the work",
+		Response: models.ExecutionPlan{}},
+	{ID: "RejectBeadPlan", Method: http.MethodPost, Path: "/api/v1/beads/{id}/plan/reject", Tag: "beads", Summary: "Rejects a bead's proposed plan so its agent plans again with the reason as feedback",
+		Request: models.RejectPlanRequest{}, Response: models.ExecutionPlan{}},
+	{ID: "ListBeadDispatches", Method: http.MethodGet, Path: "/api/v1/beads/{id}/dispatches", Tag: "beads", Summary: "Lists a bead's recorded dispatches, oldest first",
+		Response: []database.DispatchSnapshot{}},
+	{ID: "RevertDispatch", Method: http.MethodPost, Path: "/api/v1/beads/{id}/dispatches/{dispatch_id}/revert", Tag: "beads", Summary: "Rolls back a dispatch's commits, branches and PR and restores the bead's prior state",
+		Response: dispatch.RevertDispatchResult{}},
+	{ID: "UndoBead", Method: http.MethodPost, Path: "/api/v1/beads/{id}/undo", Tag: "beads", Summary: "Reverts everything a bead did: closes its PRs, deletes its branches and reverts its commits newest first",
+		Request: models.UndoBeadRequest{}, Response: loompkg.UndoBeadResult{}},
+	{ID: "ListDispatchArtifacts", Method: http.MethodGet, Path: "/api/v1/beads/{id}/dispatches/{dispatch_id}/artifacts", Tag: "beads", Summary: "Lists the prompts, responses, diffs and test logs recorded for a dispatch",
+		Response: []artifacts.Artifact{}},
+	{ID: "VerifyDispatchArtifacts", Method: http.MethodGet, Path: "/api/v1/beads/{id}/dispatches/{dispatch_id}/artifacts/verify", Tag: "beads", Summary: "Re-hashes every artifact of a dispatch against its digest",
+		Response: artifacts.VerifyResult{}},
+	{ID: "GetResolvedPrompt", Method: http.MethodGet, Path: "/api/v1/artifacts/{digest}/prompt", Tag: "beads", Summary: "Returns the exact messages a prompt artifact sent to the model",
+		Response: ResolvedPrompt{}},
+
+	{ID: "ListDecisions", Method: http.MethodGet, Path: "/api/v1/decisions", Tag: "decisions", Summary: "Lists decisions",
+		Query: []apispec.Param{
+			{Name: "status", Description: "Only decisions with this status"},
+			{Name: "priority", Description: "Only decisions with this priority (0-3)"},
+		},
+		Response: []models.DecisionBead{}},
+	{ID: "GetDecision", Method: http.MethodGet, Path: "/api/v1/decisions/{id}", Tag: "decisions", Summary: "Returns a decision",
+		Response: models.DecisionBead{}},
+	{ID: "Decide", Method: http.MethodPost, Path: "/api/v1/decisions/{id}/decide", Tag: "decisions", Summary: "Resolves a decision",
+		Request: models.DecideRequest{}, Response: models.StatusResponse{}},
+
+	{ID: "ListFileLocks", Method: http.MethodGet, Path: "/api/v1/file-locks", Tag: "beads", Summary: "Lists file locks held by agents",
+		Query:    []apispec.Param{{Name: "project_id", Description: "Only locks in this project"}},
+		Response: []models.FileLock{}},
+	{ID: "LockFile", Method: http.MethodPost, Path: "/api/v1/file-locks", Tag: "beads", Summary: "Locks a file for an agent; a file_path ending in / locks the directory. 409 when another agent holds it or waiting would deadlock",
+		Request: models.FileLockRequest{}, Response: models.FileLock{}, Status: http.StatusCreated},
+	{ID: "GetWorkGraph", Method: http.MethodGet, Path: "/api/v1/work-graph", Tag: "beads", Summary: "Returns the bead dependency graph",
+		Query:    []apispec.Param{{Name: "project_id", Description: "Only beads of this project"}},
+		Response: models.WorkGraph{}},
+
+	{ID: "Search", Method: http.MethodGet, Path: "/api/v1/search", Tag: "system", Summary: "Searches activities and beads, with their comments, for every word of q; best matches first",
+		Query: []apispec.Param{
+			{Name: "q", Description: "Words to search for; each also matches longer words it starts"},
+			{Name: "kind", Description: "activity or bead; both by default"},
+			{Name: "project_id", Description: "Only results of this project"},
+			{Name: "actor_id", Description: "Only activities by, or beads assigned to, this actor"},
+			{Name: "since", Description: "Only results at or after this RFC 3339 time"},
+			{Name: "until", Description: "Only results at or before this RFC 3339 time"},
+			{Name: "limit", Description: "At most this many results, 20 by default and 100 at most"},
+		},
+		Response: SearchResponse{}},
+
+	{ID: "ListProviders", Method: http.MethodGet, Path: "/api/v1/providers", Tag: "providers", Summary: "Lists model providers",
+		Response: []internalmodels.Provider{}},
+	{ID: "RegisterProvider", Method: http.MethodPost, Path: "/api/v1/providers", Tag: "providers", Summary: "Registers a model provider",
+		Request: ProviderRequest{}, Response: internalmodels.Provider{}, Status: http.StatusCreated},
+	{ID: "GetProviderMetrics", Method: http.MethodGet, Path: "/api/v1/providers/{id}/metrics", Tag: "providers", Summary: "Returns a provider's current score and the history of the latency, error rate, model size and cost metrics behind it",
+		Query: []apispec.Param{
+			{Name: "since", Description: "RFC 3339 time of the oldest snapshot, 24 hours ago by default"},
+			{Name: "limit", Description: "At most this many of the newest snapshots, 500 by default"},
+		},
+		Response: ProviderMetricsResponse{}},
+	{ID: "GetProviderQueue", Method: http.MethodGet, Path: "/api/v1/providers/{id}/queue", Tag: "providers", Summary: "Returns a provider's request queue: requests in flight, and per priority lane those waiting, served and shed and their average wait",
+		Response: provider.QueueStats{}},
+	{ID: "GetProviderCapabilities", Method: http.MethodGet, Path: "/api/v1/providers/{id}/capabilities", Tag: "providers", Summary: "Returns what a provider's model supports, with its model tier as a custom capability, for providers whose protocol reports it",
+		Response: pkgplugin.Capabilities{}},
+	{ID: "ListModelPrices", Method: http.MethodGet, Path: "/api/v1/models/pricing", Tag: "providers", Summary: "Lists the per-million-token prices requests are costed at, or with model set returns the price one model is costed at",
+		Query: []apispec.Param{
+			{Name: "model", Description: "Return only the price this model is costed at"},
+			{Name: "provider_id", Description: "With model, prefer a price set for this provider"},
+		},
+		Response: map[string][]pricing.Price{}},
+	{ID: "SetModelPrice", Method: http.MethodPut, Path: "/api/v1/models/pricing", Tag: "providers", Summary: "Sets a model's price; prices set here are kept when the dataset is refreshed",
+		Request: pricing.Price{}, Response: pricing.Price{}},
+	{ID: "DeleteModelPrice", Method: http.MethodDelete, Path: "/api/v1/models/pricing", Tag: "providers", Summary: "Deletes a price set through the API, so the model goes back to the dataset's price",
+		Query: []apispec.Param{
+			{Name: "model", Description: "Model whose price to delete", Required: true},
+			{Name: "provider_id", Description: "Provider the price was set for; empty for any provider"},
+		}},
+	{ID: "RefreshModelPrices", Method: http.MethodPost, Path: "/api/v1/models/pricing/refresh", Tag: "providers", Summary: "Reloads the price dataset and configured prices now and reports what changed",
+		Response: pricing.RefreshReport{}},
+	{ID: "GetEmbeddingStatus", Method: http.MethodGet, Path: "/api/v1/models/embeddings", Tag: "providers", Summary: "Reports the embedding version lessons are searched with, how many lessons are still to be re-embedded with it and the embedding cache's hit rate",
+		Response: memory.ReembedStatus{}},
+	{ID: "ReembedLessons", Method: http.MethodPost, Path: "/api/v1/models/embeddings/reembed", Tag: "providers", Summary: "Starts re-embedding lessons stored by an earlier embedder now, in the background at the configured rate",
+		Response: memory.ReembedStatus{}, Status: http.StatusAccepted},
+	{ID: "ListBenchmarkTasks", Method: http.MethodGet, Path: "/api/v1/benchmarks/tasks", Tag: "providers", Summary: "Lists the golden tasks providers are benchmarked on, by tier",
+		Response: []benchmark.Task{}},
+	{ID: "ListBenchmarkRuns", Method: http.MethodGet, Path: "/api/v1/benchmarks/runs", Tag: "providers", Summary: "Lists benchmark runs, newest first",
+		Query: []apispec.Param{
+			{Name: "limit", Description: "At most this many runs, 20 by default"},
+		},
+		Response: []benchmark.Run{}},
+	{ID: "RunBenchmark", Method: http.MethodPost, Path: "/api/v1/benchmarks/runs", Tag: "providers", Summary: "Benchmarks the given providers, or every active one, on the golden tasks now and feeds their latency and cost into provider scoring",
+		Request: BenchmarkRequest{}, Response: benchmark.Run{}},
+	{ID: "GetBenchmarkRun", Method: http.MethodGet, Path: "/api/v1/benchmarks/runs/{id}", Tag: "providers", Summary: "Returns a benchmark run with every provider's per-task results",
+		Response: benchmark.Run{}},
+
+	{ID: "ListEventWebhooks", Method: http.MethodGet, Path: "/api/v1/event-webhooks", Tag: "system", Summary: "Lists outbound event webhook subscriptions",
+		Response: []eventhooks.Subscription{}},
+	{ID: "CreateEventWebhook", Method: http.MethodPost, Path: "/api/v1/event-webhooks", Tag: "system", Summary: "Subscribes an external endpoint to activity events; the response is the only time the signing secret is shown",
+		Request: eventhooks.SubscriptionRequest{}, Response: eventhooks.Subscription{}, Status: http.StatusCreated},
+	{ID: "GetEventWebhook", Method: http.MethodGet, Path: "/api/v1/event-webhooks/{id}", Tag: "system", Summary: "Returns an event webhook subscription",
+		Response: eventhooks.Subscription{}},
+	{ID: "UpdateEventWebhook", Method: http.MethodPut, Path: "/api/v1/event-webhooks/{id}", Tag: "system", Summary: "Updates the given fields of an event webhook subscription",
+		Request: eventhooks.SubscriptionRequest{}, Response: eventhooks.Subscription{}},
+	{ID: "DeleteEventWebhook", Method: http.MethodDelete, Path: "/api/v1/event-webhooks/{id}", Tag: "system", Summary: "Deletes an event webhook subscription and its delivery log"},
+	{ID: "TestEventWebhook", Method: http.MethodPost, Path: "/api/v1/event-webhooks/{id}/test", Tag: "system", Summary: "Sends a ping event and returns the first attempt's outcome",
+		Response: eventhooks.Delivery{}},
+	{ID: "ListWebhookDeliveries", Method: http.MethodGet, Path: "/api/v1/event-webhooks/{id}/deliveries", Tag: "system", Summary: "Pages through a subscription's delivery log",
+		Query: []apispec.Param{
+			{Name: "status", Description: "pending, delivered or failed"},
+			{Name: "sort", Description: "created_at; prefix with - for descending (the default)"},
+			{Name: "limit", Description: "Page size, 50 by default"},
+			{Name: "cursor", Description: "Page to return, from the Link header of the previous page"},
+		},
+		Response: WebhookDeliveryPage{}},
+	{ID: "RedeliverWebhook", Method: http.MethodPost, Path: "/api/v1/event-webhooks/{id}/deliveries/{delivery_id}/redeliver", Tag: "system", Summary: "Sends an earlier delivery's payload again as a new delivery",
+		Response: eventhooks.Delivery{}},
+
+	{ID: "GetLogLevels", Method: http.MethodGet, Path: "/api/v1/logs/levels", Tag: "system", Summary: "Returns the default log level and per-module overrides",
+		Response: logging.LevelSettings{}},
+	{ID: "UpdateLogLevels", Method: http.MethodPut, Path: "/api/v1/logs/levels", Tag: "system", Summary: "Changes log levels until the next restart; an empty module level removes its override",
+		Request: LogLevelsRequest{}, Response: logging.LevelSettings{}},
+
+	{ID: "ListReportSchedules", Method: http.MethodGet, Path: "/api/v1/report-schedules", Tag: "system", Summary: "Lists scheduled reports",
+		Query:    []apispec.Param{{Name: "org_id", Description: "Only this organization's schedules"}},
+		Response: []reports.Schedule{}},
+	{ID: "CreateReportSchedule", Method: http.MethodPost, Path: "/api/v1/report-schedules", Tag: "system", Summary: "Schedules a report for delivery to email, Slack or S3",
+		Request: reports.ScheduleRequest{}, Response: reports.Schedule{}, Status: http.StatusCreated},
+	{ID: "GetReportSchedule", Method: http.MethodGet, Path: "/api/v1/report-schedules/{id}", Tag: "system", Summary: "Returns a scheduled report",
+		Response: reports.Schedule{}},
+	{ID: "UpdateReportSchedule", Method: http.MethodPut, Path: "/api/v1/report-schedules/{id}", Tag: "system", Summary: "Updates the given fields of a scheduled report",
+		Request: reports.ScheduleRequest{}, Response: reports.Schedule{}},
+	{ID: "DeleteReportSchedule", Method: http.MethodDelete, Path: "/api/v1/report-schedules/{id}", Tag: "system", Summary: "Deletes a scheduled report and its run log"},
+	{ID: "RunReportSchedule", Method: http.MethodPost, Path: "/api/v1/report-schedules/{id}/run", Tag: "system", Summary: "Generates and delivers a scheduled report now",
+		Response: reports.Run{}},
+	{ID: "ListReportRuns", Method: http.MethodGet, Path: "/api/v1/report-schedules/{id}/runs", Tag: "system", Summary: "Pages through a scheduled report's run log",
+		Query: []apispec.Param{
+			{Name: "status", Description: "succeeded, partial or failed"},
+			{Name: "sort", Description: "started_at; prefix with - for descending (the default)"},
+			{Name: "limit", Description: "Page size, 50 by default"},
+			{Name: "cursor", Description: "Page to return, from the Link header of the previous page"},
+		},
+		Response: ReportRunPage{}},
+
+	{ID: "GetConfigReload", Method: http.MethodGet, Path: "/api/v1/config/reload", Tag: "system", Summary: "Lists the settings that change without a restart and the result of the last reload",
+		Response: ConfigReloadStatus{}},
+	{ID: "ReloadConfig", Method: http.MethodPost, Path: "/api/v1/config/reload", Tag: "system", Summary: "Reads the config file again and applies the settings that change without a restart; an invalid file is rejected",
+		Response: loompkg.ReloadResult{}},
+
+	{ID: "ListBackups", Method: http.MethodGet, Path: "/api/v1/backups", Tag: "system", Summary: "Lists database and key store backups, newest first",
+		Response: []backup.Backup{}},
+	{ID: "CreateBackup", Method: http.MethodPost, Path: "/api/v1/backups", Tag: "system", Summary: "Backs up the database and key store now, verifies the copy and prunes old backups",
+		Response: backup.Backup{}, Status: http.StatusCreated},
+	{ID: "GetBackup", Method: http.MethodGet, Path: "/api/v1/backups/{id}", Tag: "system", Summary: "Returns a backup's manifest",
+		Response: backup.Backup{}},
+	{ID: "DeleteBackup", Method: http.MethodDelete, Path: "/api/v1/backups/{id}", Tag: "system", Summary: "Deletes a backup"},
+	{ID: "VerifyBackup", Method: http.MethodPost, Path: "/api/v1/backups/{id}/verify", Tag: "system", Summary: "Checks a backup's files against their checksums and its database dump's integrity and schema version",
+		Response: backup.Verification{}},
+
+	{ID: "ListGoldenPrompts", Method: http.MethodGet, Path: "/api/v1/projects/{id}/golden-prompts", Tag: "projects", Summary: "Lists a project's golden prompt cases",
+		Response: []goldenprompts.Case{}},
+	{ID: "CreateGoldenPrompt", Method: http.MethodPost, Path: "/api/v1/projects/{id}/golden-prompts", Tag: "projects", Summary: "Adds a golden prompt case to a project",
+		Request: goldenprompts.CaseRequest{}, Response: goldenprompts.Case{}, Status: http.StatusCreated},
+	{ID: "GetGoldenPrompt", Method: http.MethodGet, Path: "/api/v1/projects/{id}/golden-prompts/{case_id}", Tag: "projects", Summary: "Returns a golden prompt case",
+		Response: goldenprompts.Case{}},
+	{ID: "UpdateGoldenPrompt", Method: http.MethodPut, Path: "/api/v1/projects/{id}/golden-prompts/{case_id}", Tag: "projects", Summary: "Updates the fields set in the request",
+		Request: goldenprompts.CaseRequest{}, Response: goldenprompts.Case{}},
+	{ID: "DeleteGoldenPrompt", Method: http.MethodDelete, Path: "/api/v1/projects/{id}/golden-prompts/{case_id}", Tag: "projects", Summary: "Deletes a golden prompt case"},
+	{ID: "RunGoldenPrompts", Method: http.MethodPost, Path: "/api/v1/projects/{id}/golden-prompts/run", Tag: "projects", Summary: "Runs a project's golden prompts on their providers and compares the answers with the previous run",
+		Response: goldenprompts.Run{}},
+	{ID: "ListGoldenPromptRuns", Method: http.MethodGet, Path: "/api/v1/projects/{id}/golden-prompts/runs", Tag: "projects", Summary: "Lists a project's golden prompt runs, newest first",
+		Query:    []apispec.Param{{Name: "limit", Description: "Number of runs, 20 by default"}},
+		Response: []goldenprompts.Run{}},
+	{ID: "GetGoldenPromptRun", Method: http.MethodGet, Path: "/api/v1/projects/{id}/golden-prompts/runs/{run_id}", Tag: "projects", Summary: "Returns a golden prompt run with every result",
+		Response: goldenprompts.Run{}},
+
+	{ID: "ListPRReviews", Method: http.MethodGet, Path: "/api/v1/projects/{id}/pr-reviews", Tag: "projects", Summary: "Lists a project's automated pull request reviews, newest first",
+		Query:    []apispec.Param{{Name: "limit", Description: "Number of reviews, 20 by default"}},
+		Response: []prreview.Review{}},
+	{ID: "ReviewPullRequest", Method: http.MethodPost, Path: "/api/v1/projects/{id}/pr-reviews", Tag: "projects", Summary: "Reviews a pull request now, posts the findings on it and merges it if the review is clean and auto-merge is on",
+		Request: prreview.PullRequest{}, Response: prreview.Review{}},
+	{ID: "GetPRReview", Method: http.MethodGet, Path: "/api/v1/projects/{id}/pr-reviews/{review_id}", Tag: "projects", Summary: "Returns an automated pull request review with its findings",
+		Response: prreview.Review{}},
+
+	{ID: "ListJudges", Method: http.MethodGet, Path: "/api/v1/projects/{id}/judges", Tag: "projects", Summary: "Lists the judges that grade a project's dispatches",
+		Response: []evaluation.Judge{}},
+	{ID: "CreateJudge", Method: http.MethodPost, Path: "/api/v1/projects/{id}/judges", Tag: "projects", Summary: "Adds a judge persona and the criteria it grades by to a project",
+		Request: evaluation.JudgeRequest{}, Response: evaluation.Judge{}, Status: http.StatusCreated},
+	{ID: "GetJudge", Method: http.MethodGet, Path: "/api/v1/projects/{id}/judges/{judge_id}", Tag: "projects", Summary: "Returns a judge",
+		Response: evaluation.Judge{}},
+	{ID: "UpdateJudge", Method: http.MethodPut, Path: "/api/v1/projects/{id}/judges/{judge_id}", Tag: "projects", Summary: "Updates the fields set in the request",
+		Request: evaluation.JudgeRequest{}, Response: evaluation.Judge{}},
+	{ID: "DeleteJudge", Method: http.MethodDelete, Path: "/api/v1/projects/{id}/judges/{judge_id}", Tag: "projects", Summary: "Deletes a judge; its evaluations are kept"},
+	{ID: "ListEvaluations", Method: http.MethodGet, Path: "/api/v1/projects/{id}/evaluations", Tag: "projects", Summary: "Lists the judges' grades of a project's dispatches, newest first",
+		Query: []apispec.Param{
+			{Name: "bead_id", Description: "Only evaluations of this bead"},
+			{Name: "provider_id", Description: "Only evaluations of this provider's work"},
+			{Name: "limit", Description: "Number of evaluations, 50 by default"},
+		},
+		Response: []evaluation.Evaluation{}},
+
+	{ID: "ListIssueLinks", Method: http.MethodGet, Path: "/api/v1/projects/{id}/issue-sync", Tag: "projects", Summary: "Lists the tracker issues a project imported as beads, most recently synced first",
+		Response: []issuesync.Link{}},
+	{ID: "SyncIssues", Method: http.MethodPost, Path: "/api/v1/projects/{id}/issue-sync", Tag: "projects", Summary: "Imports the issues changed in the project's tracker since its last sync",
+		Response: issuesync.Result{}},
+	{ID: "ListLessonsTouching", Method: http.MethodGet, Path: "/api/v1/projects/{id}/knowledge/lessons", Tag: "projects", Summary: "Lists the lessons linked to the files at or under a path, by mentioning one or through a commit of the bead they came from",
+		Query: []apispec.Param{
+			{Name: "path", Description: "File or directory in the project's repository; repeat for several", Required: true},
+		},
+		Response: []loompkg.KnowledgeLesson{}},
+	{ID: "GetKnowledgeGraph", Method: http.MethodGet, Path: "/api/v1/projects/{id}/knowledge/graph", Tag: "projects", Summary: "Returns the lessons, beads, agents, commits and files within a few links of a node",
+		Query: []apispec.Param{
+			{Name: "kind", Description: "lesson, bead, agent, commit or file", Required: true},
+			{Name: "node", Description: "The node's ID: a lesson, bead or agent ID, a commit SHA or a file path", Required: true},
+			{Name: "depth", Description: "Links to follow out from the node, 1 by default and at most 3"},
+		},
+		Response: knowledge.Subgraph{}},
+	{ID: "IndexKnowledgeGraph", Method: http.MethodPost, Path: "/api/v1/projects/{id}/knowledge/index", Tag: "projects", Summary: "Links the project's recent bead commits and lessons into the knowledge graph now",
+		Response: knowledge.IndexReport{}},
+	{ID: "ListSupersededLessons", Method: http.MethodGet, Path: "/api/v1/projects/{id}/lessons/superseded", Tag: "projects", Summary: "Lists the lessons superseded by newer lessons contradicting them, most recently superseded first",
+		Query: []apispec.Param{
+			{Name: "limit", Description: "Most lessons to return, 50 by default"},
+		},
+		Response: []models.Lesson{}},
+	{ID: "GetLessonEffectiveness", Method: http.MethodGet, Path: "/api/v1/projects/{id}/lessons/effectiveness", Tag: "projects", Summary: "Ranks the lessons by how much more or less often the dispatches they went into succeeded than the project's dispatches overall",
+		Query: []apispec.Param{
+			{Name: "min_injections", Description: "Dispatches a lesson must have gone into to be ranked, 3 by default"},
+			{Name: "limit", Description: "Most lessons listed as helpful and as useless, 10 by default"},
+		},
+		Response: models.LessonEffectivenessReport{}},
+	{ID: "ProjectChat", Method: http.MethodPost, Path: "/api/v1/projects/{id}/chat", Tag: "projects", Summary: "Asks the project's agent a question; it can read files, search and read git history but change nothing. Set stream for server-sent events",
+		Request: models.ChatRequest{}, Response: chat.Reply{}},
+	{ID: "GetProjectChat", Method: http.MethodGet, Path: "/api/v1/projects/{id}/chat/{session_id}", Tag: "projects", Summary: "Returns the transcript of one of the caller's chat sessions",
+		Response: chat.Session{}},
+	{ID: "EndProjectChat", Method: http.MethodDelete, Path: "/api/v1/projects/{id}/chat/{session_id}", Tag: "projects", Summary: "Ends one of the caller's chat sessions"},
+	{ID: "PromoteLesson", Method: http.MethodPost, Path: "/api/v1/projects/{id}/lessons/{lesson_id}/promote", Tag: "projects", Summary: "Proposes a lesson for the organization's shared tier, optionally reworded; it is shared once approved",
+		Request: models.PromoteLessonRequest{}, Response: models.Lesson{}, Status: http.StatusCreated},
+	{ID: "ReinstateLesson", Method: http.MethodPost, Path: "/api/v1/projects/{id}/lessons/{lesson_id}/reinstate", Tag: "projects", Summary: "Puts a superseded lesson back into agents' prompts, for a contradiction that was misjudged",
+		Response: models.Lesson{}},
+	{ID: "ListPatches", Method: http.MethodGet, Path: "/api/v1/projects/{id}/patches", Tag: "projects", Summary: "Lists the agent commits the project holds for approval as proposed patches, newest first",
+		Query:    []apispec.Param{{Name: "status", Description: "Only patches in this status: pending, approved or rejected"}},
+		Response: []models.ProposedPatch{}},
+	{ID: "ApproveLowRiskPatches", Method: http.MethodPost, Path: "/api/v1/projects/{id}/patches/approve-low-risk", Tag: "projects", Summary: "Approves every pending patch whose files all match the given globs, or the project's low-risk globs",
+		Request: models.ApproveLowRiskPatchesRequest{}, Response: []models.ProposedPatch{}},
+	{ID: "GetPatch", Method: http.MethodGet, Path: "/api/v1/projects/{id}/patches/{patch_id}", Tag: "projects", Summary: "Returns a proposed patch with its diff",
+		Response: models.ProposedPatch{}},
+	{ID: "ApprovePatch", Method: http.MethodPost, Path: "/api/v1/projects/{id}/patches/{patch_id}/approve", Tag: "projects", Summary: "Approves a proposed patch so the agent's commit of it goes through",
+		Response: models.ProposedPatch{}},
+	{ID: "RejectPatch", Method: http.MethodPost, Path: "/api/v1/projects/{id}/patches/{patch_id}/reject", Tag: "projects", Summary: "Rejects a proposed patch so the agent reworks its changes, told the reason",
+		Request: models.RejectPatchRequest{}, Response: models.ProposedPatch{}},
+	{ID: "ScanProjectForOnboarding", Method: http.MethodPost, Path: "/api/v1/projects/{id}/onboarding-scan", Tag: "projects", Summary: "Scans the project's working copy for missing tests, TODOs, outdated dependencies and lint violations and files a prioritized backlog, or only proposes it on a dry run",
+		Request: models.OnboardingScanRequest{}, Response: onboarding.Report{}},
+	{ID: "CheckDependencyUpdates", Method: http.MethodPost, Path: "/api/v1/projects/{id}/dependency-updates", Tag: "projects", Summary: "Checks the project for outdated Go modules and npm packages and files a bead per ecosystem and semver risk whose pull request carries the changelogs, or only reports the groups on a dry run",
+		Request: models.DependencyUpdatesRequest{}, Response: depupdates.Report{}},
+	{ID: "ListLanguageServers", Method: http.MethodGet, Path: "/api/v1/projects/{id}/language-servers", Tag: "projects", Summary: "Lists the language servers running for the project's code navigation and rename actions",
+		Response: []lsp.ServerStatus{}},
+	{ID: "StopLanguageServers", Method: http.MethodDelete, Path: "/api/v1/projects/{id}/language-servers", Tag: "projects", Summary: "Stops the project's language servers; the next agent request starts them again"},
+
+	{ID: "ListDemoProjects", Method: http.MethodGet, Path: "/api/v1/demo", Tag: "projects", Summary: "Lists the demo projects provisioned since startup",
+		Response: []demo.Project{}},
+	{ID: "ProvisionDemo", Method: http.MethodPost, Path: "/api/v1/demo", Tag: "projects", Summary: "Provisions a demo project on a synthetic repository with seeded bugs",
+		Response: demo.Project{}, Status: http.StatusCreated},
+	{ID: "ListDemoPullRequests", Method: http.MethodGet, Path: "/api/v1/demo/{id}/pulls", Tag: "projects", Summary: "Lists a demo project's pull requests and their reviews",
+		Response: []demo.PullRequest{}},
+
+	{ID: "ListBeadCosts", Method: http.MethodGet, Path: "/api/v1/analytics/bead-costs", Tag: "analytics", Summary: "Lists the cost of each bead's requests in a period, highest first, with the bead's commits and pull requests",
+		Query: []apispec.Param{
+			{Name: "project_id", Description: "Only beads of this project"},
+			{Name: "start_time", Description: "RFC3339 start of the period; 30 days ago by default"},
+			{Name: "end_time", Description: "RFC3339 end of the period; now by default"},
+			{Name: "format", Description: "csv for a CSV export; JSON otherwise"},
+		},
+		Response: []analytics.BeadCost{}},
+	{ID: "ListCostEstimates", Method: http.MethodGet, Path: "/api/v1/analytics/estimates", Tag: "analytics", Summary: "Lists the cost estimates made before dispatches, newest first, with what finished dispatches actually took",
+		Query: []apispec.Param{
+			{Name: "bead_id", Description: "Only dispatches of this bead"},
+			{Name: "project_id", Description: "Only dispatches of this project"},
+			{Name: "completed", Description: "true for only finished dispatches"},
+			{Name: "limit", Description: "At most this many estimates, 100 by default"},
+		},
+		Response: []costestimate.Estimate{}},
+	{ID: "GetCostEstimateAccuracy", Method: http.MethodGet, Path: "/api/v1/analytics/estimates/accuracy", Tag: "analytics", Summary: "Reports, per task complexity, the iterations and token factor estimates assume and how far off recent estimates were",
+		Response: []costestimate.Calibration{}},
+	{ID: "GetProviderQuality", Method: http.MethodGet, Path: "/api/v1/analytics/quality", Tag: "analytics", Summary: "Reports the mean judge grade of each provider's work within the evaluation window",
+		Response: []evaluation.ProviderQuality{}},
+	{ID: "ListPluginPanels", Method: http.MethodGet, Path: "/api/v1/plugins/panels", Tag: "analytics", Summary: "Lists the dashboard panels contributed by all loaded plugins",
+		Response: []plugin.NamespacedPanel{}},
+	{ID: "ListPluginPanelsByPlugin", Method: http.MethodGet, Path: "/api/v1/plugins/{id}/panels", Tag: "analytics", Summary: "Lists one plugin's dashboard panels and their data schemas",
+		Response: []plugin.NamespacedPanel{}},
+	{ID: "GetPluginPanelData", Method: http.MethodGet, Path: "/api/v1/plugins/{id}/panels/{panel_id}", Tag: "analytics", Summary: "Fetches a panel's data from its plugin, checked against the panel's schema; other query parameters are passed to the plugin",
+		Response: pkgplugin.PanelData{}},
+}
+
+// OpenAPIDocument returns the OpenAPI 3.1 document of the API as JSON
+func OpenAPIDocument() ([]byte, error) {
+	return json.MarshalIndent(apispec.BuildSpec(apiInfo, apiOperations), "", "  ")
+}
+
+// OpenAPIYAML returns the same document as YAML
+func OpenAPIYAML() ([]byte, error) {
+	return yaml.Marshal(apispec.BuildSpec(apiInfo, apiOperations))
+}
+
+// GenerateClient returns the source of the generated part of pkg/client
+func GenerateClient() ([]byte, error) {
+	return apispec.GenerateClient("client", apiOperations)
+}
+
+// handleOpenAPISpec handles GET /openapi.json
+func (s *Server) handleOpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	s.respondJSON(w, http.StatusOK, apispec.BuildSpec(apiInfo, apiOperations))
+}
+
+// handleOpenAPIYAML handles GET /api/openapi.yaml, the document in YAML
+func (s *Server) handleOpenAPIYAML(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	body, err := OpenAPIYAML()
+	if err != nil {
+		s.respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Write(body)
+}
+
+// apiDocsPage renders /openapi.json with Swagger UI
+const apiDocsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Loom API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: '/openapi.json', dom_id: '#swagger-ui' });
+  </script>
+</body>
+</html>
+`
+
+// handleAPIDocs handles GET /api/docs
+func (s *Server) handleAPIDocs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.respondError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(apiDocsPage))
+}
@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// Every documented operation must be served by a route in SetupRoutes
+func TestAPIOperations_MatchRoutes(t *testing.T) {
+	s := NewServer(nil, nil, nil, &config.Config{})
+	mux := s.routes()
+
+	seen := make(map[string]bool)
+	for _, op := range apiOperations {
+		key := op.Method + " " + op.Path
+		if seen[key] {
+			t.Errorf("%s documented twice", key)
+		}
+		seen[key] = true
+
+		path := pathParamPattern(op.Path)
+		req := httptest.NewRequest(op.Method, path, nil)
+		if _, pattern := mux.Handler(req); pattern == "" {
+			t.Errorf("%s (%s) has no route", op.ID, key)
+		}
+	}
+}
+
+func pathParamPattern(path string) string {
+	for {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			return path
+		}
+		end := strings.Index(path[start:], "}")
+		path = path[:start] + "x" + path[start+end+1:]
+	}
+}
+
+// The checked-in document and client must match the route table; run
+// `go generate ./internal/api` when this fails
+func TestGeneratedAPIFilesUpToDate(t *testing.T) {
+	spec, err := OpenAPIDocument()
+	if err != nil {
+		t.Fatalf("OpenAPIDocument failed: %v", err)
+	}
+	specYAML, err := OpenAPIYAML()
+	if err != nil {
+		t.Fatalf("OpenAPIYAML failed: %v", err)
+	}
+	client, err := GenerateClient()
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+
+	for file, want := range map[string][]byte{
+		"../../api/openapi.json":           append(spec, '\n'),
+		"../../api/openapi.yaml":           specYAML,
+		"../../pkg/client/zz_generated.go": client,
+	} {
+		got, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("read %s: %v", file, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s is stale; run go generate ./internal/api", file)
+		}
+	}
+}
+
+func TestHandleOpenAPISpec(t *testing.T) {
+	s := NewServer(nil, nil, nil, &config.Config{})
+	handler := s.SetupRoutes()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	var doc struct {
+		OpenAPI string                 `json:"openapi"`
+		Paths   map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if doc.OpenAPI != "3.1.0" || doc.Paths["/api/v1/beads/{id}"] == nil {
+		t.Errorf("unexpected document: openapi=%s, %d paths", doc.OpenAPI, len(doc.Paths))
+	}
+
+	for _, path := range []string{"/api/openapi.yaml", "/api/docs"} {
+		rec = httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", path, rec.Code)
+		}
+	}
+}
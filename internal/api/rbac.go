@@ -0,0 +1,206 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+)
+
+// routeResources maps API path prefixes to the permission resource that
+// guards them. Longest prefix wins; unlisted paths only need a login.
+var routeResources = []struct {
+	prefix   string
+	resource string
+}{
+	{"/api/v1/auth/users", "users"},
+	{"/api/v1/orgs", "orgs"},
+	{"/api/v1/audit", "audit"},
+	{"/api/v1/config", "config"},
+	{"/api/v1/config/reload", "config-reload"},
+	{"/api/v1/personas", "agents"},
+	{"/api/v1/agents", "agents"},
+	{"/api/v1/org-charts", "agents"},
+	{"/api/v1/projects", "projects"},
+	{"/api/v1/project-templates", "projects"},
+	{"/api/v1/file-locks", "projects"},
+	{"/api/v1/work-graph", "projects"},
+	{"/api/v1/federation", "projects"},
+	{"/api/v1/demo", "projects"},
+	{"/api/v1/beads", "beads"},
+	{"/api/v1/artifacts", "beads"},
+	{"/api/v1/comments", "beads"},
+	{"/api/v1/conversations", "beads"},
+	{"/api/v1/work", "beads"},
+	{"/api/v1/decisions", "decisions"},
+	{"/api/v1/providers", "providers"},
+	{"/api/v1/routing", "providers"},
+	{"/api/v1/models", "providers"},
+	{"/api/v1/benchmarks", "providers"},
+	{"/api/v1/repl", "repl"},
+	{"/api/v1/commands", "repl"},
+	{"/api/v1/system", "system"},
+	{"/api/v1/analytics", "analytics"},
+	{"/api/v1/plugins", "analytics"},
+	{"/api/v1/cache", "system"},
+	{"/api/v1/patterns", "system"},
+	{"/api/v1/optimizations", "system"},
+	{"/api/v1/prompts", "system"},
+	{"/api/v1/logs", "system"},
+	{"/api/v1/logs/levels", "log-levels"},
+	{"/api/v1/events", "system"},
+	{"/api/v1/activity-feed", "system"},
+	{"/api/v1/activity-feed/retention", "activity-retention"},
+	{"/api/v1/search", "system"},
+	{"/api/v1/motivations", "system"},
+	{"/api/v1/workflows", "system"},
+	{"/api/v1/webhooks", "system"},
+	{"/api/v1/event-webhooks", "system"},
+	{"/api/v1/report-schedules", "system"},
+	{"/api/v1/backups", "backups"},
+	{"/api/v1/notifications/oncall", "oncall"},
+	{"/api/v1/openclaw", "system"},
+	{"/metrics", "system"},
+}
+
+// routePermission is the RBAC policy for the HTTP API: reads need
+// "<resource>:read", everything else "<resource>:write". User management,
+// the audit log, log levels, config reloads, backups, activity compaction
+// runs, on-call chain changes and organization changes need admin rights,
+// and the REPL needs "repl:use". The project returned, which lets
+// project-scoped role bindings apply, comes only from a /projects/{id} path;
+// every other route needs a global grant.
+func routePermission(r *http.Request) (string, string) {
+	resource := ""
+	matched := 0
+	for _, rr := range routeResources {
+		if len(rr.prefix) > matched && (r.URL.Path == rr.prefix || strings.HasPrefix(r.URL.Path, rr.prefix+"/")) {
+			resource, matched = rr.resource, len(rr.prefix)
+		}
+	}
+
+	switch resource {
+	case "":
+		return "", ""
+	case "users":
+		if isReadMethod(r.Method) {
+			return "users:read", ""
+		}
+		return "users:admin", ""
+	case "audit", "log-levels", "config-reload", "backups":
+		return "system:admin", ""
+	case "orgs", "oncall":
+		if isReadMethod(r.Method) {
+			return "", ""
+		}
+		return "system:admin", ""
+	case "activity-retention":
+		if isReadMethod(r.Method) {
+			return "system:read", ""
+		}
+		return "system:admin", ""
+	case "repl":
+		return "repl:use", ""
+	case "projects":
+		// A policy dry run changes nothing, so readers may try policies,
+		// and the chat agent only reads the project
+		if strings.HasSuffix(r.URL.Path, "/policy/evaluate") || pathProjectAction(r) == "chat" {
+			return "projects:read", pathProjectID(r)
+		}
+	}
+	if isReadMethod(r.Method) {
+		return resource + ":read", pathProjectID(r)
+	}
+	return resource + ":write", pathProjectID(r)
+}
+
+// orgRoutes are the API path prefixes open to callers confined to an
+// organization. Everything else spans organizations, such as agents,
+// analytics, system status and configuration, and is left to the default
+// organization.
+var orgRoutes = []string{
+	"/api/v1/auth",
+	"/api/v1/orgs",
+	"/api/v1/projects",
+	"/api/v1/project-templates",
+	"/api/v1/beads",
+	"/api/v1/providers",
+	"/api/v1/activity-feed",
+	"/api/v1/activity-views",
+	"/api/v1/notifications",
+	"/api/v1/search",
+}
+
+// confineToOrg refuses callers confined to an organization any route that
+// is not in orgRoutes
+func confineToOrg(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.GetOrgIDFromRequest(r) != "" && !orgRoute(r.URL.Path) {
+			http.Error(w, "Not available to organization members", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func orgRoute(path string) bool {
+	for _, prefix := range orgRoutes {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// pathProjectID returns the project a /projects/{id} path names. Only the
+// path counts: a project_id parameter or body field is the caller's claim,
+// and trusting it would let a project role grant rights on global
+// resources.
+func pathProjectID(r *http.Request) string {
+	if rest := strings.TrimPrefix(r.URL.Path, "/api/v1/projects/"); rest != r.URL.Path {
+		if id := strings.SplitN(rest, "/", 2)[0]; id != "" && id != "bootstrap" && id != "git" {
+			return id
+		}
+	}
+	return ""
+}
+
+// pathProjectAction returns the sub-resource a /projects/{id}/... path
+// names, such as "chat"
+func pathProjectAction(r *http.Request) string {
+	if pathProjectID(r) == "" {
+		return ""
+	}
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/api/v1/projects/"), "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[1]
+}
+
+// routePolicy is routePermission with the project of a /beads/{id} path
+// looked up from the bead itself, so project roles apply to existing beads
+func (s *Server) routePolicy(r *http.Request) (string, string) {
+	permission, projectID := routePermission(r)
+	if projectID != "" || !strings.HasPrefix(permission, "beads:") {
+		return permission, projectID
+	}
+	rest := strings.TrimPrefix(r.URL.Path, "/api/v1/beads/")
+	if rest == r.URL.Path {
+		return permission, ""
+	}
+	id := strings.SplitN(rest, "/", 2)[0]
+	if id == "" || id == "auto-file" || id == "workflow" || s.app == nil {
+		return permission, ""
+	}
+	if mgr := s.app.GetBeadsManager(); mgr != nil {
+		if bead, err := mgr.GetBead(id); err == nil && bead != nil {
+			return permission, bead.ProjectID
+		}
+	}
+	return permission, ""
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRoutePermission(t *testing.T) {
+	tests := []struct {
+		method, path   string
+		wantPermission string
+		wantProject    string
+	}{
+		{http.MethodGet, "/api/v1/beads", "beads:read", ""},
+		{http.MethodDelete, "/api/v1/beads/b-1", "beads:write", ""},
+		{http.MethodGet, "/api/v1/projects/proj-1/files/tree", "projects:read", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/git/sync", "projects:write", ""},
+		{http.MethodPut, "/api/v1/projects/proj-1/policy", "projects:write", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/proj-1/policy/evaluate", "projects:read", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/proj-1/chat", "projects:read", "proj-1"},
+		{http.MethodDelete, "/api/v1/projects/proj-1/chat/s-1", "projects:read", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/proj-1/patches/patch-1/approve", "projects:write", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/proj-1/onboarding-scan", "projects:write", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/proj-1/dependency-updates", "projects:write", "proj-1"},
+		{http.MethodGet, "/api/v1/projects/proj-1/language-servers", "projects:read", "proj-1"},
+		{http.MethodDelete, "/api/v1/projects/proj-1/language-servers", "projects:write", "proj-1"},
+		{http.MethodPost, "/api/v1/projects/proj-1/golden-prompts/run", "projects:write", "proj-1"},
+		{http.MethodGet, "/api/v1/projects/proj-1/golden-prompts/runs/r-1/report", "projects:read", "proj-1"},
+		{http.MethodGet, "/api/v1/work-graph?project_id=proj-2", "projects:read", ""},
+		{http.MethodPut, "/api/v1/config", "config:write", ""},
+		{http.MethodGet, "/api/v1/audit/export", "system:admin", ""},
+		{http.MethodGet, "/api/v1/logs/recent", "system:read", ""},
+		{http.MethodPut, "/api/v1/logs/levels", "system:admin", ""},
+		{http.MethodGet, "/api/v1/backups", "system:admin", ""},
+		{http.MethodPost, "/api/v1/config/reload", "system:admin", ""},
+		{http.MethodGet, "/api/v1/activity-feed/rollups", "system:read", ""},
+		{http.MethodGet, "/api/v1/activity-feed/a-1/events", "system:read", ""},
+		{http.MethodGet, "/api/v1/activity-feed/retention", "system:read", ""},
+		{http.MethodPost, "/api/v1/activity-feed/retention", "system:admin", ""},
+		{http.MethodGet, "/api/v1/search?q=deploy", "system:read", ""},
+		{http.MethodPost, "/api/v1/backups/20261015T120000Z-abcd1234/verify", "system:admin", ""},
+		{http.MethodPut, "/api/v1/auth/users/u-1/roles", "users:admin", ""},
+		{http.MethodPost, "/api/v1/repl", "repl:use", ""},
+		{http.MethodGet, "/api/v1/analytics/costs", "analytics:read", ""},
+		{http.MethodGet, "/api/v1/plugins/metrics/panels/queue", "analytics:read", ""},
+		{http.MethodGet, "/api/v1/auth/me", "", ""},
+		{http.MethodGet, "/api/v1/notifications", "", ""},
+		{http.MethodPut, "/api/v1/activity-views/v-1/subscription", "", ""},
+		{http.MethodGet, "/api/v1/orgs/acme", "", ""},
+		{http.MethodPut, "/api/v1/orgs/acme", "system:admin", ""},
+		{http.MethodGet, "/api/v1/notifications/oncall/proj-1", "", ""},
+		{http.MethodPut, "/api/v1/notifications/oncall/proj-1", "system:admin", ""},
+		{http.MethodGet, "/api/v1/project-templates", "projects:read", ""},
+		{http.MethodPost, "/api/v1/project-templates/t1/instantiate", "projects:write", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		perm, project := routePermission(req)
+		if perm != tt.wantPermission || project != tt.wantProject {
+			t.Errorf("%s %s = (%q, %q), want (%q, %q)", tt.method, tt.path, perm, project, tt.wantPermission, tt.wantProject)
+		}
+	}
+}
+
+func TestRoutePermission_IgnoresClaimedProject(t *testing.T) {
+	// A project role must not reach global resources by naming the project
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/config?project_id=proj-3", strings.NewReader(`{"project_id":"proj-3"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if perm, project := routePermission(req); perm != "config:write" || project != "" {
+		t.Errorf("got (%q, %q), want (config:write, \"\")", perm, project)
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/beads", strings.NewReader(`{"title":"Fix login","project_id":"proj-3"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if perm, project := routePermission(req); perm != "beads:write" || project != "" {
+		t.Errorf("got (%q, %q), want (beads:write, \"\")", perm, project)
+	}
+}
+
+func TestConfineToOrg(t *testing.T) {
+	handler := confineToOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		orgID, path string
+		want        int
+	}{
+		{"acme", "/api/v1/projects/proj-1", http.StatusOK},
+		{"acme", "/api/v1/activity-feed/stream", http.StatusOK},
+		{"acme", "/api/v1/search", http.StatusOK},
+		{"acme", "/api/v1/activity-views/v-1", http.StatusOK},
+		{"acme", "/api/v1/auth/users", http.StatusOK},
+		{"acme", "/api/v1/project-templates/t1/instantiate", http.StatusOK},
+		{"acme", "/api/v1/agents", http.StatusForbidden},
+		{"acme", "/api/v1/analytics/costs", http.StatusForbidden},
+		{"acme", "/api/v1/config", http.StatusForbidden},
+		{"acme", "/api/v1/projectsx", http.StatusForbidden},
+		{"", "/api/v1/agents", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.orgID != "" {
+			req.Header.Set("X-Org-ID", tt.orgID)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%q %s = %d, want %d", tt.orgID, tt.path, rec.Code, tt.want)
+		}
+	}
+}
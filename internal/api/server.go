@@ -1,9 +1,12 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"net/http"
+	"os"
 	"strings"
 	"sync"
 	"time"
@@ -18,6 +21,8 @@ import (
 	"github.com/jordanhubbard/loom/internal/metrics"
 	"github.com/jordanhubbard/loom/pkg/config"
 	"github.com/jordanhubbard/loom/pkg/models"
+	"github.com/jordanhubbard/loom/web"
+	"github.com/google/uuid"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -29,6 +34,7 @@ type Server struct {
 	analyticsLogger *analytics.Logger
 	logManager      *logging.Manager
 	cache           *cache.Cache
+	cacheWarmer     *cache.Warmer
 	config          *config.Config
 	fileManager     *files.Manager
 	metrics         *metrics.Metrics
@@ -48,10 +54,21 @@ type Server struct {
 func NewServer(arb *loom.Loom, km *keymanager.KeyManager, am *auth.Manager, cfg *config.Config) *Server {
 	// Initialize analytics logger with default privacy config
 	var analyticsLogger *analytics.Logger
+	var analyticsStorage analytics.Storage
 	if arb != nil && arb.GetDatabase() != nil {
 		storage, err := analytics.NewDatabaseStorage(arb.GetDatabase().DB())
 		if err == nil {
-			analyticsLogger = analytics.NewLogger(storage, analytics.DefaultPrivacyConfig())
+			analyticsStorage = storage
+			privacy := analytics.DefaultPrivacyConfig()
+			if cfg != nil && cfg.Cache.Enabled && cfg.Cache.Warm.Enabled {
+				// Warming replays logged responses; compliance constraints still apply per project
+				privacy.LogRequestBodies = true
+				privacy.LogResponseBodies = true
+			}
+			analyticsLogger = analytics.NewLogger(storage, privacy)
+			analyticsLogger.SetComplianceLookup(arb.ProjectCompliance)
+			analyticsLogger.SetLiveMetrics(arb.GetLiveMetrics())
+			analyticsLogger.SetPricer(arb.GetPricing())
 		}
 	}
 
@@ -70,6 +87,14 @@ func NewServer(arb *loom.Loom, km *keymanager.KeyManager, am *auth.Manager, cfg
 			MaxSize:       cfg.Cache.MaxSize,
 			MaxMemoryMB:   cfg.Cache.MaxMemoryMB,
 			CleanupPeriod: cfg.Cache.CleanupPeriod,
+
+			Compression:      cfg.Cache.Compression,
+			CompressMinBytes: cfg.Cache.CompressMinBytes,
+			Keys: cache.KeyConfig{
+				Canonicalize:           cfg.Cache.Keys.Canonicalize,
+				StripFields:            cfg.Cache.Keys.StripFields,
+				IgnoreTemperatureBelow: cfg.Cache.Keys.IgnoreTemperatureBelow,
+			},
 		}
 		// Use defaults if not specified
 		if cacheConfig.DefaultTTL == 0 {
@@ -96,6 +121,23 @@ func NewServer(arb *loom.Loom, km *keymanager.KeyManager, am *auth.Manager, cfg
 		} else {
 			responseCache = cache.New(cacheConfig)
 		}
+		if arb != nil {
+			responseCache.SetPricer(arb.GetPricing())
+		}
+	}
+
+	// Warm the cache from the analytics history when configured
+	var cacheWarmer *cache.Warmer
+	if responseCache != nil && analyticsStorage != nil && cfg.Cache.Warm.Enabled {
+		warm := cfg.Cache.Warm
+		cacheWarmer = cache.NewWarmer(analyticsStorage, responseCache, &cache.WarmConfig{
+			TimeWindow:     warm.TimeWindow,
+			MaxEntries:     warm.MaxEntries,
+			Strategy:       warm.Strategy,
+			MinOccurrences: warm.MinOccurrences,
+			TTL:            warm.TTL,
+			Interval:       warm.Interval,
+		})
 	}
 
 	var fileManager *files.Manager
@@ -105,6 +147,20 @@ func NewServer(arb *loom.Loom, km *keymanager.KeyManager, am *auth.Manager, cfg
 
 	// Initialize Prometheus metrics
 	promMetrics := metrics.NewMetrics()
+	if responseCache != nil {
+		promMetrics.SetCacheStatsSource(func() metrics.CacheStats {
+			stats := responseCache.GetStats(context.Background())
+			return metrics.CacheStats{Hits: stats.Hits, Misses: stats.Misses, HitRate: stats.HitRate}
+		})
+	}
+	if arb != nil {
+		if embeddingCache := arb.GetEmbeddingCache(); embeddingCache != nil {
+			promMetrics.SetEmbeddingCacheStatsSource(func() metrics.EmbeddingCacheStats {
+				stats := embeddingCache.Stats()
+				return metrics.EmbeddingCacheStats{MemoryHits: stats.MemoryHits, StoreHits: stats.StoreHits, Misses: stats.Misses, HitRate: stats.HitRate}
+			})
+		}
+	}
 
 	return &Server{
 		app:             arb,
@@ -113,6 +169,7 @@ func NewServer(arb *loom.Loom, km *keymanager.KeyManager, am *auth.Manager, cfg
 		analyticsLogger: analyticsLogger,
 		logManager:      logMgr,
 		cache:           responseCache,
+		cacheWarmer:     cacheWarmer,
 		config:          cfg,
 		fileManager:     fileManager,
 		metrics:         promMetrics,
@@ -120,29 +177,55 @@ func NewServer(arb *loom.Loom, km *keymanager.KeyManager, am *auth.Manager, cfg
 	}
 }
 
+// StartCacheWarmer warms the cache from the analytics history now and on
+// the configured schedule until ctx is done. It does nothing unless cache
+// warming is enabled.
+func (s *Server) StartCacheWarmer(ctx context.Context) {
+	if s.cacheWarmer != nil {
+		s.cacheWarmer.Start(ctx)
+	}
+}
+
 // SetupRoutes configures HTTP routes
 func (s *Server) SetupRoutes() http.Handler {
+	mux := s.routes()
+
+	// Apply middleware
+	handler := s.loggingMiddleware(mux)
+	handler = s.corsMiddleware(handler)
+	handler = s.authMiddleware(handler)
+
+	return handler
+}
+
+// routes registers the API's handlers
+func (s *Server) routes() *http.ServeMux {
 	mux := http.NewServeMux()
 
 	// Serve static files
 	if s.config.WebUI.Enabled {
-		fs := http.FileServer(http.Dir(s.config.WebUI.StaticPath))
-		mux.Handle("/static/", http.StripPrefix("/static/", fs))
+		static := s.staticFiles()
+		mux.Handle("/static/", http.StripPrefix("/static/", http.FileServerFS(static)))
 
 		// Serve index.html at root
 		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 			if r.URL.Path == "/" {
-				http.ServeFile(w, r, s.config.WebUI.StaticPath+"/index.html")
+				http.ServeFileFS(w, r, static, "index.html")
 			} else {
 				http.NotFound(w, r)
 			}
 		})
+
+		// Live dashboard of beads, activity, notifications, decisions and providers
+		mux.HandleFunc("/dashboard", func(w http.ResponseWriter, r *http.Request) {
+			http.ServeFileFS(w, r, static, "dashboard.html")
+		})
 	}
 
-	// Serve OpenAPI spec
-	mux.HandleFunc("/api/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
-		http.ServeFile(w, r, "./api/openapi.yaml")
-	})
+	// OpenAPI document, generated from apiOperations, and its Swagger UI
+	mux.HandleFunc("/openapi.json", s.handleOpenAPISpec)
+	mux.HandleFunc("/api/openapi.yaml", s.handleOpenAPIYAML)
+	mux.HandleFunc("/api/docs", s.handleAPIDocs)
 
 	// Health check
 	mux.HandleFunc("/api/v1/health", s.handleHealth)
@@ -155,7 +238,29 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux.HandleFunc("/api/v1/auth/login", authHandlers.HandleLogin)
 	mux.HandleFunc("/api/v1/auth/refresh", authHandlers.HandleRefreshToken)
 	mux.HandleFunc("/api/v1/auth/change-password", authHandlers.HandleChangePassword)
-	mux.HandleFunc("/api/v1/auth/api-keys", authHandlers.HandleCreateAPIKey)
+	mux.HandleFunc("/api/v1/auth/oidc", authHandlers.HandleOIDCInfo)
+	mux.HandleFunc("/api/v1/auth/oidc/login", authHandlers.HandleOIDCLogin)
+	mux.HandleFunc("/api/v1/auth/oidc/callback", authHandlers.HandleOIDCCallback)
+	mux.HandleFunc("/api/v1/auth/api-keys", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			authHandlers.HandleCreateAPIKey(w, r)
+		case http.MethodGet:
+			authHandlers.HandleListAPIKeys(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc("/api/v1/auth/api-keys/", func(w http.ResponseWriter, r *http.Request) {
+		// /api/v1/auth/api-keys/{id} and /api/v1/auth/api-keys/{id}/rotate
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v1/auth/api-keys/")
+		keyID, action, _ := strings.Cut(rest, "/")
+		if keyID == "" {
+			http.NotFound(w, r)
+			return
+		}
+		authHandlers.HandleAPIKey(w, r, keyID, action)
+	})
 	mux.HandleFunc("/api/v1/auth/me", authHandlers.HandleGetCurrentUser)
 	mux.HandleFunc("/api/v1/auth/users", func(w http.ResponseWriter, r *http.Request) {
 		switch r.Method {
@@ -167,6 +272,21 @@ func (s *Server) SetupRoutes() http.Handler {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		}
 	})
+	mux.HandleFunc("/api/v1/auth/users/", func(w http.ResponseWriter, r *http.Request) {
+		// /api/v1/auth/users/{id}/roles
+		rest := strings.TrimPrefix(r.URL.Path, "/api/v1/auth/users/")
+		userID, sub, _ := strings.Cut(rest, "/")
+		if userID == "" || sub != "roles" {
+			http.NotFound(w, r)
+			return
+		}
+		authHandlers.HandleUserRoles(w, r, userID)
+	})
+	mux.HandleFunc("/api/v1/auth/roles", authHandlers.HandleListRoles)
+
+	// Organizations
+	mux.HandleFunc("/api/v1/orgs", s.handleOrgs)
+	mux.HandleFunc("/api/v1/orgs/", s.handleOrg)
 
 	// Personas
 	mux.HandleFunc("/api/v1/personas", s.handlePersonas)
@@ -187,6 +307,7 @@ func (s *Server) SetupRoutes() http.Handler {
 	// Beads
 	mux.HandleFunc("/api/v1/beads", s.handleBeads)
 	mux.HandleFunc("/api/v1/beads/", s.handleBead)
+	mux.HandleFunc("/api/v1/artifacts/", s.handleArtifact)
 
 	// Federation
 	mux.HandleFunc("/api/v1/federation/status", s.handleFederationStatus)
@@ -221,6 +342,16 @@ func (s *Server) SetupRoutes() http.Handler {
 
 	// Models
 	mux.HandleFunc("/api/v1/models/recommended", s.handleRecommendedModels)
+	mux.HandleFunc("/api/v1/models/deprecations", s.handleModelDeprecations)
+	mux.HandleFunc("/api/v1/models/metadata", s.handleModelMetadata)
+	mux.HandleFunc("/api/v1/models/pricing", s.handleModelPricing)
+	mux.HandleFunc("/api/v1/models/pricing/refresh", s.handleRefreshModelPricing)
+	mux.HandleFunc("/api/v1/models/embeddings", s.handleEmbeddingStatus)
+	mux.HandleFunc("/api/v1/models/embeddings/reembed", s.handleReembedLessons)
+	mux.HandleFunc("/api/v1/audit", s.handleAuditLog)
+	mux.HandleFunc("/api/v1/audit/verify", s.handleAuditVerify)
+	mux.HandleFunc("/api/v1/audit/export", s.handleAuditExport)
+	mux.HandleFunc("/api/v1/models/migrations", s.handleModelMigrations)
 
 	// System
 	mux.HandleFunc("/api/v1/system/status", s.handleSystemStatus)
@@ -243,6 +374,7 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux.HandleFunc("/api/v1/logs/recent", s.HandleLogsRecent)
 	mux.HandleFunc("/api/v1/logs/stream", s.HandleLogsStream)
 	mux.HandleFunc("/api/v1/logs/export", s.HandleLogsExport)
+	mux.HandleFunc("/api/v1/logs/levels", s.handleLogLevels)
 
 	// Chat completions (with streaming support)
 	mux.HandleFunc("/api/v1/chat/completions/stream", s.handleStreamChatCompletion)
@@ -261,15 +393,23 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux.HandleFunc("/api/v1/analytics/logs", s.handleGetLogs)
 	mux.HandleFunc("/api/v1/analytics/stats", s.handleGetLogStats)
 	mux.HandleFunc("/api/v1/analytics/export", s.handleExportLogs)
+	mux.HandleFunc("/api/v1/analytics/logs/export", s.handleStreamLogExport)
 	mux.HandleFunc("/api/v1/analytics/export-stats", s.handleExportStats)
 	mux.HandleFunc("/api/v1/analytics/costs", s.handleGetCostReport)
 	mux.HandleFunc("/api/v1/analytics/batching", s.handleGetBatchingRecommendations)
+	mux.HandleFunc("/api/v1/analytics/anomalies/drilldown", s.handleGetCostDrilldown)
+	mux.HandleFunc("/api/v1/analytics/bead-costs", s.handleGetBeadCosts)
+	mux.HandleFunc("/api/v1/analytics/estimates", s.handleGetCostEstimates)
+	mux.HandleFunc("/api/v1/analytics/estimates/accuracy", s.handleGetCostEstimateAccuracy)
+	mux.HandleFunc("/api/v1/analytics/live", s.handleLiveMetrics)
+	mux.HandleFunc("/api/v1/analytics/quality", s.handleGetProviderQuality)
 
 	// Cache management
 	mux.HandleFunc("/api/v1/cache/stats", s.handleGetCacheStats)
 	mux.HandleFunc("/api/v1/cache/config", s.handleGetCacheConfig)
 	mux.HandleFunc("/api/v1/cache/clear", s.handleClearCache)
 	mux.HandleFunc("/api/v1/cache/invalidate", s.handleInvalidateCache)
+	mux.HandleFunc("/api/v1/cache/warm", s.handleCacheWarm)
 
 	// Cache analysis and optimization
 	mux.HandleFunc("/api/v1/cache/analysis", s.handleCacheAnalysis)
@@ -296,6 +436,7 @@ func (s *Server) SetupRoutes() http.Handler {
 	mux.HandleFunc("/api/v1/config", s.handleConfig)
 	mux.HandleFunc("/api/v1/config/export.yaml", s.handleConfigExportYAML)
 	mux.HandleFunc("/api/v1/config/import.yaml", s.handleConfigImportYAML)
+	mux.HandleFunc("/api/v1/config/reload", s.handleConfigReload)
 
 	// Events (real-time updates and event bus)
 	mux.HandleFunc("/api/v1/events/stream", s.handleEventStream)
@@ -306,13 +447,29 @@ func (s *Server) SetupRoutes() http.Handler {
 	// Activity feed
 	mux.HandleFunc("/api/v1/activity-feed", s.handleGetActivityFeed)
 	mux.HandleFunc("/api/v1/activity-feed/stream", s.handleActivityFeedStream)
+	mux.HandleFunc("/api/v1/activity-feed/rollups", s.handleActivityRollups)
+	mux.HandleFunc("/api/v1/activity-feed/retention", s.handleActivityRetention)
+	mux.HandleFunc("/api/v1/activity-feed/", s.handleActivity)
+	mux.HandleFunc("/api/v1/activity-views", s.handleActivityViews)
+	mux.HandleFunc("/api/v1/activity-views/", s.handleActivityView)
+
+	// Search
+	mux.HandleFunc("/api/v1/search", s.handleSearch)
 
 	// Notifications
 	mux.HandleFunc("/api/v1/notifications", s.handleGetNotifications)
 	mux.HandleFunc("/api/v1/notifications/stream", s.handleNotificationStream)
 	mux.HandleFunc("/api/v1/notifications/", s.handleNotificationActions)
 	mux.HandleFunc("/api/v1/notifications/mark-all-read", s.handleMarkAllRead)
+	mux.HandleFunc("/api/v1/notifications/unread-counts", s.handleUnreadCounts)
 	mux.HandleFunc("/api/v1/notifications/preferences", s.handleNotificationPreferences)
+	mux.HandleFunc("/api/v1/notifications/templates", s.handleNotificationTemplates)
+	mux.HandleFunc("/api/v1/notifications/mutes", s.handleNotificationMutes)
+	mux.HandleFunc("/api/v1/notifications/mutes/", s.handleNotificationMutes)
+	mux.HandleFunc("/api/v1/notifications/devices", s.handleNotificationDevices)
+	mux.HandleFunc("/api/v1/notifications/devices/", s.handleNotificationDevices)
+	mux.HandleFunc("/api/v1/notifications/escalations", s.handleNotificationEscalations)
+	mux.HandleFunc("/api/v1/notifications/oncall/", s.handleOnCallChain)
 
 	// Motivations
 	mux.HandleFunc("/api/v1/motivations", s.handleMotivations)
@@ -331,18 +488,34 @@ func (s *Server) SetupRoutes() http.Handler {
 
 	// Webhooks (external event integration)
 	mux.HandleFunc("/api/v1/webhooks/github", s.handleGitHubWebhook)
+	mux.HandleFunc("/api/v1/webhooks/gitlab", s.handleGitLabWebhook)
+	mux.HandleFunc("/api/v1/webhooks/jira", s.handleJiraWebhook)
 	mux.HandleFunc("/api/v1/webhooks/openclaw", s.handleOpenClawWebhook)
 	mux.HandleFunc("/api/v1/webhooks/status", s.handleWebhookStatus)
 
+	// Outbound event webhooks
+	mux.HandleFunc("/api/v1/event-webhooks", s.handleEventWebhooks)
+	mux.HandleFunc("/api/v1/event-webhooks/", s.handleEventWebhook)
+	mux.HandleFunc("/api/v1/report-schedules", s.handleReportSchedules)
+	mux.HandleFunc("/api/v1/report-schedules/", s.handleReportSchedule)
+	mux.HandleFunc("/api/v1/backups", s.handleBackups)
+	mux.HandleFunc("/api/v1/backups/", s.handleBackup)
+	mux.HandleFunc("/api/v1/benchmarks/", s.handleBenchmarks)
+
+	// Demo mode
+	mux.HandleFunc("/api/v1/project-templates", s.handleProjectTemplates)
+	mux.HandleFunc("/api/v1/project-templates/", s.handleProjectTemplate)
+	mux.HandleFunc("/api/v1/demo", s.handleDemo)
+	mux.HandleFunc("/api/v1/demo/", s.handleDemoProject)
+
+	// Plugin-contributed dashboard panels
+	mux.HandleFunc("/api/v1/plugins/panels", s.handlePluginPanels)
+	mux.HandleFunc("/api/v1/plugins/", s.handlePlugin)
+
 	// OpenClaw messaging gateway
 	mux.HandleFunc("/api/v1/openclaw/status", s.handleOpenClawStatus)
 
-	// Apply middleware
-	handler := s.loggingMiddleware(mux)
-	handler = s.corsMiddleware(handler)
-	handler = s.authMiddleware(handler)
-
-	return handler
+	return mux
 }
 
 // handleHealth handles health check requests
@@ -357,11 +530,30 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 
 // Middleware
 
-// loggingMiddleware logs HTTP requests
+// apiLog is the structured logger of the HTTP API
+var apiLog = logging.Logger("api")
+
+// loggingMiddleware tags each request with an ID, echoed in X-Request-ID and
+// attached to every log record written while serving it, and logs it
 func (s *Server) loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.New().String()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+		fields := []any{"request_id", requestID}
+		if user := s.getUserFromContext(r); user != nil {
+			fields = append(fields, "user_id", user.ID)
+		}
+		r = r.WithContext(logging.WithFields(r.Context(), fields...))
+
+		start := time.Now()
 		recorder := &statusRecorder{ResponseWriter: w}
 		next.ServeHTTP(recorder, r)
+		apiLog.DebugContext(r.Context(), "request served",
+			"method", r.Method, "path", r.URL.Path, "status", recorder.statusCode,
+			"duration_ms", time.Since(start).Milliseconds())
 		s.recordAPIFailure(r, recorder.statusCode)
 	})
 }
@@ -536,6 +728,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization")
+		w.Header().Set("Access-Control-Expose-Headers", "Link, X-Total-Count")
 
 		// Handle preflight
 		if r.Method == http.MethodOptions {
@@ -557,8 +750,14 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			r.URL.Path == "/health/ready" ||
 			r.URL.Path == "/api/v1/auth/login" ||
 			r.URL.Path == "/api/v1/auth/refresh" ||
+			r.URL.Path == "/api/v1/auth/oidc" ||
+			r.URL.Path == "/api/v1/auth/oidc/login" ||
+			r.URL.Path == "/api/v1/auth/oidc/callback" ||
 			r.URL.Path == "/" ||
+			r.URL.Path == "/dashboard" ||
 			r.URL.Path == "/api/openapi.yaml" ||
+			r.URL.Path == "/openapi.json" ||
+			r.URL.Path == "/api/docs" ||
 			r.URL.Path == "/api/v1/events/stream" ||
 			r.URL.Path == "/api/v1/chat/completions/stream" ||
 			r.URL.Path == "/api/v1/chat/completions" ||
@@ -574,15 +773,28 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			r.Header.Set("X-User-ID", "admin")
 			r.Header.Set("X-Username", "admin")
 			r.Header.Set("X-Role", "admin")
+			r.Header.Del("X-Org-ID")
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		// Apply JWT/API key auth
-		s.authManager.Middleware("")(next).ServeHTTP(w, r)
+		// Apply JWT/API key auth and role-based authorization
+		s.authManager.RequirePermission(s.routePolicy)(confineToOrg(next)).ServeHTTP(w, r)
 	})
 }
 
+// staticFiles returns the web UI to serve: the files under
+// web_ui.static_path when that directory exists, so the UI can be edited
+// without rebuilding, and otherwise the copy embedded in the binary
+func (s *Server) staticFiles() fs.FS {
+	if dir := s.config.WebUI.StaticPath; dir != "" {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return os.DirFS(dir)
+		}
+	}
+	return web.Static()
+}
+
 // Helper functions
 
 // getUserFromContext extracts the user from request headers (set by auth middleware)
@@ -618,7 +830,7 @@ func (s *Server) respondJSON(w http.ResponseWriter, status int, data interface{}
 
 // respondError writes an error response
 func (s *Server) respondError(w http.ResponseWriter, status int, message string) {
-	s.respondJSON(w, status, map[string]string{"error": message})
+	s.respondJSON(w, status, models.ErrorResponse{Error: message})
 }
 
 // parseJSON parses JSON request body
@@ -322,6 +322,37 @@ func TestServer_SetupRoutes(t *testing.T) {
 	}
 }
 
+func TestServer_SetupRoutes_EmbeddedWebUI(t *testing.T) {
+	cfg := &config.Config{
+		WebUI: config.WebUIConfig{
+			Enabled:    true,
+			StaticPath: t.TempDir() + "/missing", // Falls back to the embedded UI
+		},
+	}
+	handler := NewServer(nil, nil, nil, cfg).SetupRoutes()
+
+	for path, want := range map[string]string{
+		"/":                         "<title>Loom",
+		"/dashboard":                "Loom Live Dashboard",
+		"/static/js/dashboard.js":   "/notifications/stream",
+		"/static/workflows.html":    "Workflow System",
+		"/static/css/style.css":     "",
+		"/static/does-not-exist.js": "",
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if path == "/static/does-not-exist.js" {
+			if w.Code != http.StatusNotFound {
+				t.Errorf("GET %s = %d, want 404", path, w.Code)
+			}
+			continue
+		}
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), want) {
+			t.Errorf("GET %s = %d, want 200 containing %q", path, w.Code, want)
+		}
+	}
+}
+
 func TestServer_ConcurrentResponses(t *testing.T) {
 	server := &Server{}
 	done := make(chan bool)
@@ -0,0 +1,221 @@
+package apispec
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// GenerateClient renders Go client methods for ops into package pkg. The
+// methods call c.do, which the package implements by hand alongside the
+// generated file. Operations whose body types live in internal packages
+// are skipped, since callers outside this module could not name them.
+func GenerateClient(pkg string, ops []Operation) ([]byte, error) {
+	imports := map[string]bool{"context": true}
+	var body bytes.Buffer
+
+	for _, op := range ops {
+		if !clientVisible(op.Request) || !clientVisible(op.Response) {
+			continue
+		}
+		writeMethod(&body, op, imports)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by loom-apigen from the server's route table. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\nimport (\n", pkg)
+	var std, other []string
+	for p := range imports {
+		if strings.Contains(strings.Split(p, "/")[0], ".") {
+			other = append(other, p)
+		} else {
+			std = append(std, p)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(other)
+	for _, p := range std {
+		fmt.Fprintf(&out, "\t%q\n", p)
+	}
+	if len(other) > 0 {
+		out.WriteString("\n")
+	}
+	for _, p := range other {
+		fmt.Fprintf(&out, "\t%q\n", p)
+	}
+	out.WriteString(")\n")
+	out.Write(body.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+func writeMethod(w *bytes.Buffer, op Operation, imports map[string]bool) {
+	var args []string
+	args = append(args, "ctx context.Context")
+	for _, p := range op.PathParams() {
+		args = append(args, goIdent(p, false)+" string")
+	}
+	paramsType := op.ID + "Params"
+	if len(op.Query) > 0 {
+		args = append(args, "params *"+paramsType)
+	}
+	if op.Request != nil {
+		args = append(args, "req "+typeExpr(reflect.TypeOf(op.Request), imports))
+	}
+
+	// Structs are returned by pointer, everything else by value
+	var result, outDecl, outArg string
+	if op.Response != nil {
+		t := reflect.TypeOf(op.Response)
+		if t.Kind() == reflect.Struct {
+			result = "*" + typeExpr(t, imports)
+			outDecl = "out := new(" + typeExpr(t, imports) + ")"
+			outArg = "out"
+		} else {
+			result = typeExpr(t, imports)
+			outDecl = "var out " + result
+			outArg = "&out"
+		}
+	}
+
+	if len(op.Query) > 0 {
+		imports["net/url"] = true
+		fmt.Fprintf(w, "\n// %s holds the query parameters of %s\ntype %s struct {\n", paramsType, op.ID, paramsType)
+		for _, q := range op.Query {
+			if q.Description != "" {
+				fmt.Fprintf(w, "\t%s string // %s\n", goIdent(q.Name, true), q.Description)
+			} else {
+				fmt.Fprintf(w, "\t%s string\n", goIdent(q.Name, true))
+			}
+		}
+		w.WriteString("}\n")
+	}
+
+	fmt.Fprintf(w, "\n// %s %s\n//\n// %s %s\n", op.ID, lowerFirst(op.Summary), op.Method, op.Path)
+	if result != "" {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", op.ID, strings.Join(args, ", "), result)
+		fmt.Fprintf(w, "\t%s\n", outDecl)
+	} else {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) error {\n", op.ID, strings.Join(args, ", "))
+	}
+
+	query := "nil"
+	if len(op.Query) > 0 {
+		query = "q"
+		w.WriteString("\tq := url.Values{}\n\tif params != nil {\n")
+		for _, p := range op.Query {
+			field := goIdent(p.Name, true)
+			fmt.Fprintf(w, "\t\tif params.%s != \"\" {\n\t\t\tq.Set(%q, params.%s)\n\t\t}\n", field, p.Name, field)
+		}
+		w.WriteString("\t}\n")
+	}
+
+	reqArg := "nil"
+	if op.Request != nil {
+		reqArg = "req"
+	}
+	if outArg == "" {
+		outArg = "nil"
+	}
+	call := fmt.Sprintf("c.do(ctx, %q, %s, %s, %s, %s)", op.Method, pathExpr(op, imports), query, reqArg, outArg)
+	switch {
+	case result == "":
+		fmt.Fprintf(w, "\treturn %s\n}\n", call)
+	case strings.HasPrefix(result, "*"):
+		fmt.Fprintf(w, "\tif err := %s; err != nil {\n\t\treturn nil, err\n\t}\n\treturn out, nil\n}\n", call)
+	default:
+		fmt.Fprintf(w, "\terr := %s\n\treturn out, err\n}\n", call)
+	}
+}
+
+// pathExpr renders the request path, escaping path parameters
+func pathExpr(op Operation, imports map[string]bool) string {
+	parts := pathParamPattern.Split(op.Path, -1)
+	params := op.PathParams()
+	if len(params) == 0 {
+		return fmt.Sprintf("%q", op.Path)
+	}
+	imports["net/url"] = true
+	var expr []string
+	for i, lit := range parts {
+		if lit != "" {
+			expr = append(expr, fmt.Sprintf("%q", lit))
+		}
+		if i < len(params) {
+			expr = append(expr, "url.PathEscape("+goIdent(params[i], false)+")")
+		}
+	}
+	return strings.Join(expr, " + ")
+}
+
+// typeExpr renders t as Go source, recording the imports it needs
+func typeExpr(t reflect.Type, imports map[string]bool) string {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			return t.Name()
+		}
+		imports[t.PkgPath()] = true
+		return path.Base(t.PkgPath()) + "." + t.Name()
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + typeExpr(t.Elem(), imports)
+	case reflect.Slice:
+		return "[]" + typeExpr(t.Elem(), imports)
+	case reflect.Map:
+		return "map[" + typeExpr(t.Key(), imports) + "]" + typeExpr(t.Elem(), imports)
+	case reflect.Interface:
+		return "interface{}"
+	}
+	return t.String()
+}
+
+// clientVisible reports whether callers outside the module can name v's type
+func clientVisible(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	return typeVisible(reflect.TypeOf(v))
+}
+
+func typeVisible(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array:
+		return typeVisible(t.Elem())
+	case reflect.Map:
+		return typeVisible(t.Key()) && typeVisible(t.Elem())
+	}
+	return !strings.Contains(t.PkgPath()+"/", "/internal/")
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	r := []rune(s)
+	r[0] = unicode.ToLower(r[0])
+	return string(r)
+}
+
+// goIdent converts a snake_case name to a Go identifier, keeping common
+// initialisms upper case
+func goIdent(name string, exported bool) string {
+	parts := strings.Split(name, "_")
+	for i, p := range parts {
+		if i == 0 && !exported {
+			continue
+		}
+		switch upper := strings.ToUpper(p); upper {
+		case "ID", "URL", "API":
+			parts[i] = upper
+		default:
+			parts[i] = exportedName(p)
+		}
+	}
+	return strings.Join(parts, "")
+}
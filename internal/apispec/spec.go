@@ -0,0 +1,180 @@
+// Package apispec builds an OpenAPI 3.1 document and a typed Go client
+// from a table of API operations and the Go types of their request and
+// response bodies.
+package apispec
+
+import (
+	"net/http"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operation describes one operation of the HTTP API
+type Operation struct {
+	ID       string      // operationId, and the Go client method name
+	Method   string      // HTTP method
+	Path     string      // URL path; {name} segments are path parameters
+	Tag      string      // Groups operations in the document
+	Summary  string      // One line, used as the client method's doc comment
+	Query    []Param     // Query string parameters
+	Request  interface{} // Zero value of the JSON request body type, nil for none
+	Response interface{} // Zero value of the JSON response body type, nil for none
+	Status   int         // Success status; 200, or 204 without a response body
+	Public   bool        // Callable without credentials
+}
+
+// Param is a query string parameter
+type Param struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// Info describes the API as a whole
+type Info struct {
+	Title       string
+	Version     string
+	Description string
+	Error       interface{} // Zero value of the error body type
+}
+
+// pathParamPattern matches a {name} path segment
+var pathParamPattern = regexp.MustCompile(`\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+
+// PathParams returns the names of an operation's path parameters in order
+func (op Operation) PathParams() []string {
+	var names []string
+	for _, m := range pathParamPattern.FindAllStringSubmatch(op.Path, -1) {
+		names = append(names, m[1])
+	}
+	return names
+}
+
+// SuccessStatus returns the status the operation answers with on success
+func (op Operation) SuccessStatus() int {
+	switch {
+	case op.Status != 0:
+		return op.Status
+	case op.Response == nil:
+		return http.StatusNoContent
+	}
+	return http.StatusOK
+}
+
+// BuildSpec returns the OpenAPI 3.1 document for ops, ready to be encoded
+// as JSON or YAML
+func BuildSpec(info Info, ops []Operation) map[string]interface{} {
+	schemas := newSchemaSet()
+	paths := make(map[string]interface{})
+
+	var errorSchema map[string]interface{}
+	if info.Error != nil {
+		errorSchema = schemas.schemaFor(reflect.TypeOf(info.Error))
+	}
+
+	for _, op := range ops {
+		item, _ := paths[op.Path].(map[string]interface{})
+		if item == nil {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+
+		operation := map[string]interface{}{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+		}
+		if op.Tag != "" {
+			operation["tags"] = []string{op.Tag}
+		}
+		if op.Public {
+			operation["security"] = []interface{}{}
+		}
+
+		var params []interface{}
+		for _, name := range op.PathParams() {
+			params = append(params, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		for _, q := range op.Query {
+			param := map[string]interface{}{
+				"name":   q.Name,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "string"},
+			}
+			if q.Description != "" {
+				param["description"] = q.Description
+			}
+			if q.Required {
+				param["required"] = true
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		status := op.SuccessStatus()
+		success := map[string]interface{}{"description": http.StatusText(status)}
+		if op.Response != nil {
+			success["content"] = map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": schemas.schemaFor(reflect.TypeOf(op.Response))},
+			}
+		}
+		responses := map[string]interface{}{strconv.Itoa(status): success}
+		if errorSchema != nil {
+			responses["default"] = map[string]interface{}{
+				"description": "Error",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": errorSchema},
+				},
+			}
+		}
+		operation["responses"] = responses
+
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	infoDoc := map[string]interface{}{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		infoDoc["description"] = info.Description
+	}
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info":    infoDoc,
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{
+					"type":        "http",
+					"scheme":      "bearer",
+					"description": "A JWT from /api/v1/auth/login or an API key (loom_k_...)",
+				},
+				"apiKeyAuth": map[string]interface{}{
+					"type": "apiKey",
+					"in":   "header",
+					"name": "X-API-Key",
+				},
+			},
+		},
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"apiKeyAuth": []string{}},
+		},
+	}
+}
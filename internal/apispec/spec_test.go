@@ -0,0 +1,141 @@
+package apispec
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+type embedded struct {
+	Version string `json:"version,omitempty"`
+}
+
+type widget struct {
+	embedded `json:",inline"`
+
+	ID       string            `json:"id"`
+	Size     int64             `json:"size"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Parent   *widget           `json:"parent,omitempty"`
+	Created  time.Time         `json:"created_at"`
+	Secret   string            `json:"-"`
+	internal string
+}
+
+type createWidget struct {
+	Name string `json:"name"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func TestBuildSpec_SchemasFromGoTypes(t *testing.T) {
+	spec := BuildSpec(Info{Title: "Test", Version: "1", Error: apiError{}}, []Operation{
+		{ID: "GetWidget", Method: "GET", Path: "/widgets/{id}", Response: widget{}},
+	})
+	if spec["openapi"] != "3.1.0" {
+		t.Errorf("openapi = %v", spec["openapi"])
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	w := schemas["widget"].(map[string]interface{})
+	props := w["properties"].(map[string]interface{})
+
+	for _, name := range []string{"id", "size", "labels", "parent", "created_at", "version"} {
+		if _, ok := props[name]; !ok {
+			t.Errorf("missing property %s", name)
+		}
+	}
+	for _, name := range []string{"Secret", "internal", "embedded"} {
+		if _, ok := props[name]; ok {
+			t.Errorf("unexpected property %s", name)
+		}
+	}
+	if got := strings.Join(w["required"].([]string), ","); got != "id,size,created_at" {
+		t.Errorf("required = %s", got)
+	}
+	if ref := props["parent"].(map[string]interface{})["$ref"]; ref != "#/components/schemas/widget" {
+		t.Errorf("recursive field should reference its component, got %v", ref)
+	}
+	if f := props["created_at"].(map[string]interface{})["format"]; f != "date-time" {
+		t.Errorf("time format = %v", f)
+	}
+	if _, ok := schemas["apiError"]; !ok {
+		t.Error("error type should be a component")
+	}
+}
+
+func TestBuildSpec_Operations(t *testing.T) {
+	spec := BuildSpec(Info{Title: "Test", Version: "1"}, []Operation{
+		{ID: "ListWidgets", Method: "GET", Path: "/widgets", Query: []Param{{Name: "owner"}}, Response: []widget{}, Public: true},
+		{ID: "CreateWidget", Method: "POST", Path: "/widgets", Request: createWidget{}, Response: widget{}, Status: 201},
+		{ID: "DeleteWidget", Method: "DELETE", Path: "/widgets/{id}"},
+	})
+	paths := spec["paths"].(map[string]interface{})
+
+	list := paths["/widgets"].(map[string]interface{})["get"].(map[string]interface{})
+	if sec, ok := list["security"].([]interface{}); !ok || len(sec) != 0 {
+		t.Error("public operations should clear security")
+	}
+	if params := list["parameters"].([]interface{}); len(params) != 1 {
+		t.Errorf("expected one query parameter, got %d", len(params))
+	}
+
+	create := paths["/widgets"].(map[string]interface{})["post"].(map[string]interface{})
+	if _, ok := create["requestBody"]; !ok {
+		t.Error("expected request body")
+	}
+	if _, ok := create["responses"].(map[string]interface{})["201"]; !ok {
+		t.Error("expected 201 response")
+	}
+
+	del := paths["/widgets/{id}"].(map[string]interface{})["delete"].(map[string]interface{})
+	if _, ok := del["responses"].(map[string]interface{})["204"]; !ok {
+		t.Error("operations without a response body should answer 204")
+	}
+	param := del["parameters"].([]interface{})[0].(map[string]interface{})
+	if param["in"] != "path" || param["name"] != "id" {
+		t.Errorf("unexpected path parameter: %v", param)
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	src, err := GenerateClient("client", []Operation{
+		{ID: "ListWidgets", Method: "GET", Path: "/widgets", Summary: "Lists widgets", Query: []Param{{Name: "owner_id"}}, Response: []models.Bead{}},
+		{ID: "GetWidgetPart", Method: "GET", Path: "/widgets/{id}/parts/{part_id}", Summary: "Returns a part", Response: models.Bead{}},
+		{ID: "DeleteWidget", Method: "DELETE", Path: "/widgets/{id}", Summary: "Deletes a widget"},
+	})
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+	code := string(src)
+	for _, want := range []string{
+		"type ListWidgetsParams struct",
+		"OwnerID string",
+		"// ListWidgets lists widgets",
+		"func (c *Client) ListWidgets(ctx context.Context, params *ListWidgetsParams) ([]models.Bead, error)",
+		"func (c *Client) GetWidgetPart(ctx context.Context, id string, partID string) (*models.Bead, error)",
+		`"/widgets/"+url.PathEscape(id)+"/parts/"+url.PathEscape(partID)`,
+		"func (c *Client) DeleteWidget(ctx context.Context, id string) error",
+	} {
+		if !strings.Contains(code, want) {
+			t.Errorf("generated client missing %q:\n%s", want, code)
+		}
+	}
+}
+
+func TestGenerateClient_SkipsInternalTypes(t *testing.T) {
+	src, err := GenerateClient("client", []Operation{
+		{ID: "GetWidget", Method: "GET", Path: "/widgets/{id}", Response: widget{}},
+	})
+	if err != nil {
+		t.Fatalf("GenerateClient failed: %v", err)
+	}
+	// widget lives in internal/apispec, which callers could not import
+	if strings.Contains(string(src), "GetWidget") {
+		t.Error("operations with internal types should be skipped")
+	}
+}
@@ -0,0 +1,263 @@
+// Package artifacts keeps what each dispatch showed the model and what it
+// produced — resolved prompts, responses, diffs and test logs — as
+// immutable, content-addressed blobs. Identical content is stored once, so
+// reruns that see the same prompt add no new blob data, and every blob can
+// be re-hashed to prove it is what the model saw.
+package artifacts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// Artifact kinds
+const (
+	KindPrompt   = "prompt"   // Manifest of the messages sent to the model
+	KindResponse = "response" // Raw model response
+	KindDiff     = "diff"     // Working copy changes made by the dispatch
+	KindTestLog  = "test_log" // Output of a run_tests action
+)
+
+// digestPrefix names the hash algorithm in every digest
+const digestPrefix = "sha256:"
+
+// Ref identifies the dispatch an artifact belongs to
+type Ref struct {
+	DispatchID string // The dispatched task's ID
+	BeadID     string
+	ProjectID  string
+}
+
+// Artifact is a recorded piece of a dispatch, pointing at its content blob
+type Artifact struct {
+	ID         int64     `json:"id"`
+	DispatchID string    `json:"dispatch_id"`
+	BeadID     string    `json:"bead_id"`
+	ProjectID  string    `json:"project_id"`
+	Kind       string    `json:"kind"`
+	Name       string    `json:"name,omitempty"`
+	Iteration  int       `json:"iteration,omitempty"` // Action loop iteration, 0 for the whole dispatch
+	Digest     string    `json:"digest"`
+	Size       int64     `json:"size"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// PromptManifest is the content of a prompt artifact. Each message is a
+// blob of its own, so the growing history of an action loop is stored once
+// rather than again on every iteration.
+type PromptManifest struct {
+	Model       string   `json:"model"`
+	Temperature float64  `json:"temperature"`
+	Messages    []string `json:"messages"` // Digests of the JSON-encoded messages, in order
+}
+
+// Store persists blobs and artifact records. Neither is ever updated or
+// deleted.
+type Store interface {
+	// PutArtifactBlob stores content under its digest; storing a digest
+	// that already exists is a no-op
+	PutArtifactBlob(digest string, content []byte) error
+	// GetArtifactBlob returns the content for a digest, or nil if unknown
+	GetArtifactBlob(digest string) ([]byte, error)
+	AddRunArtifact(a *Artifact) error
+	ListRunArtifacts(dispatchID string) ([]*Artifact, error)
+}
+
+// VerifyResult reports whether every blob of a dispatch still matches its digest
+type VerifyResult struct {
+	Valid    bool     `json:"valid"`
+	Checked  int      `json:"checked"`
+	Mismatch []string `json:"mismatch,omitempty"` // Digests whose content no longer hashes to them
+	Missing  []string `json:"missing,omitempty"`  // Digests with no stored content
+}
+
+// Recorder writes and reads artifacts. A nil Recorder records nothing.
+type Recorder struct {
+	store Store
+}
+
+// NewRecorder creates a recorder backed by store
+func NewRecorder(store Store) *Recorder {
+	if store == nil {
+		return nil
+	}
+	return &Recorder{store: store}
+}
+
+// Digest returns the content address of content
+func Digest(content []byte) string {
+	sum := sha256.Sum256(content)
+	return digestPrefix + hex.EncodeToString(sum[:])
+}
+
+// ValidDigest reports whether s looks like a digest made by Digest
+func ValidDigest(s string) bool {
+	hexPart := strings.TrimPrefix(s, digestPrefix)
+	if hexPart == s || len(hexPart) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hexPart)
+	return err == nil
+}
+
+// Put stores content and returns its digest
+func (r *Recorder) Put(content []byte) (string, error) {
+	digest := Digest(content)
+	if err := r.store.PutArtifactBlob(digest, content); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// Get returns the content stored under digest, refusing content that no
+// longer hashes to it
+func (r *Recorder) Get(digest string) ([]byte, error) {
+	content, err := r.store.GetArtifactBlob(digest)
+	if err != nil {
+		return nil, err
+	}
+	if content == nil {
+		return nil, fmt.Errorf("artifact %s not found", digest)
+	}
+	if Digest(content) != digest {
+		return nil, fmt.Errorf("artifact %s failed verification", digest)
+	}
+	return content, nil
+}
+
+// Record stores content as an artifact of the dispatch
+func (r *Recorder) Record(ref Ref, kind, name string, iteration int, content []byte) (*Artifact, error) {
+	if r == nil {
+		return nil, nil
+	}
+	digest, err := r.Put(content)
+	if err != nil {
+		return nil, err
+	}
+	a := &Artifact{
+		DispatchID: ref.DispatchID,
+		BeadID:     ref.BeadID,
+		ProjectID:  ref.ProjectID,
+		Kind:       kind,
+		Name:       name,
+		Iteration:  iteration,
+		Digest:     digest,
+		Size:       int64(len(content)),
+		CreatedAt:  time.Now().UTC(),
+	}
+	if err := r.store.AddRunArtifact(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// RecordPrompt stores the request sent to the model on one iteration
+func (r *Recorder) RecordPrompt(ref Ref, iteration int, req *provider.ChatCompletionRequest, messages []provider.ChatMessage) (*Artifact, error) {
+	if r == nil {
+		return nil, nil
+	}
+	manifest := PromptManifest{
+		Model:       req.Model,
+		Temperature: req.Temperature,
+		Messages:    make([]string, 0, len(messages)),
+	}
+	for _, m := range messages {
+		data, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode prompt message: %w", err)
+		}
+		digest, err := r.Put(data)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Messages = append(manifest.Messages, digest)
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode prompt manifest: %w", err)
+	}
+	return r.Record(ref, KindPrompt, "", iteration, data)
+}
+
+// ResolvePrompt rebuilds the messages of a prompt artifact
+func (r *Recorder) ResolvePrompt(digest string) (*PromptManifest, []provider.ChatMessage, error) {
+	data, err := r.Get(digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	var manifest PromptManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("artifact %s is not a prompt: %w", digest, err)
+	}
+	messages := make([]provider.ChatMessage, 0, len(manifest.Messages))
+	for _, d := range manifest.Messages {
+		raw, err := r.Get(d)
+		if err != nil {
+			return nil, nil, err
+		}
+		var m provider.ChatMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, nil, fmt.Errorf("failed to decode prompt message %s: %w", d, err)
+		}
+		messages = append(messages, m)
+	}
+	return &manifest, messages, nil
+}
+
+// List returns a dispatch's artifacts in the order they were recorded
+func (r *Recorder) List(dispatchID string) ([]*Artifact, error) {
+	return r.store.ListRunArtifacts(dispatchID)
+}
+
+// Verify re-hashes every blob a dispatch's artifacts refer to, including
+// the messages of its prompts
+func (r *Recorder) Verify(dispatchID string) (*VerifyResult, error) {
+	list, err := r.store.ListRunArtifacts(dispatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &VerifyResult{Valid: true}
+	seen := make(map[string]bool)
+	check := func(digest string) []byte {
+		if seen[digest] {
+			return nil
+		}
+		seen[digest] = true
+		result.Checked++
+		content, err := r.store.GetArtifactBlob(digest)
+		switch {
+		case err != nil || content == nil:
+			result.Missing = append(result.Missing, digest)
+		case Digest(content) != digest:
+			result.Mismatch = append(result.Mismatch, digest)
+		default:
+			return content
+		}
+		result.Valid = false
+		return nil
+	}
+
+	for _, a := range list {
+		content := check(a.Digest)
+		if a.Kind != KindPrompt || content == nil {
+			continue
+		}
+		var manifest PromptManifest
+		if err := json.Unmarshal(content, &manifest); err != nil {
+			result.Valid = false
+			result.Mismatch = append(result.Mismatch, a.Digest)
+			continue
+		}
+		for _, d := range manifest.Messages {
+			check(d)
+		}
+	}
+	return result, nil
+}
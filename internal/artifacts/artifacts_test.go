@@ -0,0 +1,150 @@
+package artifacts_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/artifacts"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "artifacts.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func blobCount(t *testing.T, db *database.Database) int {
+	t.Helper()
+	var n int
+	if err := db.DB().QueryRow(`SELECT COUNT(*) FROM artifact_blobs`).Scan(&n); err != nil {
+		t.Fatalf("failed to count blobs: %v", err)
+	}
+	return n
+}
+
+func TestRecordPromptDedupesHistory(t *testing.T) {
+	store := newTestDB(t)
+	rec := artifacts.NewRecorder(store)
+	ref := artifacts.Ref{DispatchID: "task-1", BeadID: "bd-1", ProjectID: "proj-1"}
+	req := &provider.ChatCompletionRequest{Model: "m", Temperature: 0.7}
+
+	history := []provider.ChatMessage{
+		{Role: "system", Content: "You are an engineer."},
+		{Role: "user", Content: "Fix the bug."},
+	}
+	first, err := rec.RecordPrompt(ref, 1, req, history)
+	if err != nil {
+		t.Fatalf("RecordPrompt failed: %v", err)
+	}
+	history = append(history,
+		provider.ChatMessage{Role: "assistant", Content: `{"action":"read_file"}`},
+		provider.ChatMessage{Role: "user", Content: "file contents"},
+	)
+	second, err := rec.RecordPrompt(ref, 2, req, history)
+	if err != nil {
+		t.Fatalf("RecordPrompt failed: %v", err)
+	}
+
+	// Four distinct messages plus two manifests
+	if got := blobCount(t, store); got != 6 {
+		t.Errorf("expected shared messages stored once (6 blobs), got %d", got)
+	}
+
+	manifest, messages, err := rec.ResolvePrompt(second.Digest)
+	if err != nil {
+		t.Fatalf("ResolvePrompt failed: %v", err)
+	}
+	if manifest.Model != "m" || len(messages) != 4 || messages[3].Content != "file contents" {
+		t.Errorf("unexpected resolved prompt: %+v %+v", manifest, messages)
+	}
+
+	// An identical rerun adds records but no content
+	rerun, err := rec.RecordPrompt(artifacts.Ref{DispatchID: "task-2", BeadID: "bd-1"}, 1, req, history[:2])
+	if err != nil {
+		t.Fatalf("RecordPrompt failed: %v", err)
+	}
+	if rerun.Digest != first.Digest || blobCount(t, store) != 6 {
+		t.Errorf("expected rerun to reuse %s, got %s (%d blobs)", first.Digest, rerun.Digest, blobCount(t, store))
+	}
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	store := newTestDB(t)
+	rec := artifacts.NewRecorder(store)
+	ref := artifacts.Ref{DispatchID: "task-1", BeadID: "bd-1"}
+
+	prompt, err := rec.RecordPrompt(ref, 1, &provider.ChatCompletionRequest{}, []provider.ChatMessage{{Role: "user", Content: "hi"}})
+	if err != nil {
+		t.Fatalf("RecordPrompt failed: %v", err)
+	}
+	if _, err := rec.Record(ref, artifacts.KindResponse, "", 1, []byte("hello")); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	result, err := rec.Verify("task-1")
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !result.Valid || result.Checked != 3 {
+		t.Fatalf("expected 3 valid blobs, got %+v", result)
+	}
+
+	var manifest artifacts.PromptManifest
+	if _, msgs, err := rec.ResolvePrompt(prompt.Digest); err != nil || len(msgs) != 1 {
+		t.Fatalf("ResolvePrompt failed: %v", err)
+	}
+	raw, err := store.GetArtifactBlob(prompt.Digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	// Bypass the immutability trigger the way an edit of the database file would
+	if _, err := store.DB().Exec(`DROP TRIGGER artifact_blobs_no_update`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DB().Exec(`UPDATE artifact_blobs SET content = ? WHERE digest = ?`,
+		[]byte(`{"role":"user","content":"bye"}`), manifest.Messages[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err = rec.Verify("task-1")
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Valid || len(result.Mismatch) != 1 || result.Mismatch[0] != manifest.Messages[0] {
+		t.Errorf("expected the tampered message to be reported, got %+v", result)
+	}
+	if _, _, err := rec.ResolvePrompt(prompt.Digest); err == nil {
+		t.Error("expected resolving a tampered prompt to fail")
+	}
+}
+
+func TestNilRecorderRecordsNothing(t *testing.T) {
+	var rec *artifacts.Recorder
+	if a, err := rec.Record(artifacts.Ref{}, artifacts.KindDiff, "", 0, []byte("x")); a != nil || err != nil {
+		t.Errorf("expected nil recorder to be a no-op, got %v %v", a, err)
+	}
+	if artifacts.NewRecorder(nil) != nil {
+		t.Error("expected NewRecorder(nil) to return nil")
+	}
+}
+
+func TestValidDigest(t *testing.T) {
+	if !artifacts.ValidDigest(artifacts.Digest([]byte("x"))) {
+		t.Error("expected Digest output to be valid")
+	}
+	for _, bad := range []string{"", "sha256:", "abc", "sha256:zz" + artifacts.Digest(nil)[9:11]} {
+		if artifacts.ValidDigest(bad) {
+			t.Errorf("expected %q to be invalid", bad)
+		}
+	}
+}
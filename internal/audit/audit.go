@@ -0,0 +1,262 @@
+// Package audit provides a central, tamper-evident log of privileged
+// operations. Each entry stores the hash of its predecessor, so editing or
+// deleting any persisted entry breaks the chain and is caught by Verify.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Outcomes
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomeDenied  = "denied"
+)
+
+// TimestampLayout is the fixed-width UTC layout entries are hashed and
+// stored with, so stored timestamps sort lexically
+const TimestampLayout = "2006-01-02T15:04:05.000000000Z"
+
+// genesisHash is the previous hash of the first entry
+const genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+const verifyPageSize = 1000
+
+// Event is a privileged operation to record
+type Event struct {
+	Actor     string                 `json:"actor"`              // User, agent, or subsystem that performed the operation
+	Action    string                 `json:"action"`             // Dotted name, e.g. "decision.resolve" or "git.push"
+	Resource  string                 `json:"resource,omitempty"` // ID of the affected object
+	ProjectID string                 `json:"project_id,omitempty"`
+	Outcome   string                 `json:"outcome"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+}
+
+// Entry is a recorded event with its position in the hash chain
+type Entry struct {
+	Sequence  int64     `json:"sequence"`
+	Timestamp time.Time `json:"timestamp"`
+	Event
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash"`
+}
+
+// Filter selects entries. Zero fields match everything.
+type Filter struct {
+	Actor     string
+	Action    string
+	Resource  string
+	ProjectID string
+	Outcome   string
+	Since     time.Time
+	Until     time.Time
+	AfterSeq  int64
+	Limit     int
+}
+
+// Store persists entries in sequence order
+type Store interface {
+	AppendAuditEntry(e *Entry) error
+	LastAuditEntry() (*Entry, error)
+	QueryAuditEntries(f Filter) ([]*Entry, error)
+}
+
+// VerifyResult reports the outcome of a chain verification
+type VerifyResult struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	BrokenAt int64  `json:"broken_at,omitempty"` // Sequence of the first bad entry
+	Reason   string `json:"reason,omitempty"`
+}
+
+// Logger appends events to a Store, maintaining the hash chain
+type Logger struct {
+	mu    sync.Mutex
+	store Store
+	last  *Entry
+}
+
+// NewLogger creates a logger that continues the chain already in store
+func NewLogger(store Store) (*Logger, error) {
+	if store == nil {
+		return nil, fmt.Errorf("audit store is required")
+	}
+	last, err := store.LastAuditEntry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load last audit entry: %w", err)
+	}
+	return &Logger{store: store, last: last}, nil
+}
+
+// Record appends an event to the chain
+func (l *Logger) Record(ev Event) (*Entry, error) {
+	if ev.Action == "" {
+		return nil, fmt.Errorf("audit action is required")
+	}
+	if ev.Outcome == "" {
+		ev.Outcome = OutcomeSuccess
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e := &Entry{
+		Sequence:  1,
+		Timestamp: time.Now().UTC().Truncate(time.Microsecond),
+		Event:     ev,
+		PrevHash:  genesisHash,
+	}
+	if l.last != nil {
+		e.Sequence = l.last.Sequence + 1
+		e.PrevHash = l.last.Hash
+	}
+	hash, err := ComputeHash(e)
+	if err != nil {
+		return nil, err
+	}
+	e.Hash = hash
+
+	if err := l.store.AppendAuditEntry(e); err != nil {
+		return nil, fmt.Errorf("failed to append audit entry: %w", err)
+	}
+	l.last = e
+	return e, nil
+}
+
+// Query returns entries matching the filter in sequence order
+func (l *Logger) Query(f Filter) ([]*Entry, error) {
+	return l.store.QueryAuditEntries(f)
+}
+
+// Verify walks the whole chain and checks sequence continuity, linkage and
+// each entry's hash
+func (l *Logger) Verify() (*VerifyResult, error) {
+	result := &VerifyResult{Valid: true}
+	prevHash := genesisHash
+	var after int64
+	for {
+		page, err := l.store.QueryAuditEntries(Filter{AfterSeq: after, Limit: verifyPageSize})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range page {
+			if reason := checkEntry(e, after+1, prevHash); reason != "" {
+				result.Valid = false
+				result.BrokenAt = e.Sequence
+				result.Reason = reason
+				return result, nil
+			}
+			result.Checked++
+			after = e.Sequence
+			prevHash = e.Hash
+		}
+		if len(page) < verifyPageSize {
+			return result, nil
+		}
+	}
+}
+
+func checkEntry(e *Entry, wantSeq int64, prevHash string) string {
+	if e.Sequence != wantSeq {
+		return fmt.Sprintf("expected sequence %d, found %d (entry missing)", wantSeq, e.Sequence)
+	}
+	if e.PrevHash != prevHash {
+		return "previous hash does not match the preceding entry"
+	}
+	hash, err := ComputeHash(e)
+	if err != nil {
+		return err.Error()
+	}
+	if hash != e.Hash {
+		return "entry hash does not match its contents"
+	}
+	return ""
+}
+
+// ExportJSONL writes matching entries to w, one JSON object per line, and
+// returns the number written
+func (l *Logger) ExportJSONL(w io.Writer, f Filter) (int, error) {
+	limit := f.Limit
+	f.Limit = verifyPageSize
+	enc := json.NewEncoder(w)
+	written := 0
+	for {
+		page, err := l.store.QueryAuditEntries(f)
+		if err != nil {
+			return written, err
+		}
+		for _, e := range page {
+			if limit > 0 && written >= limit {
+				return written, nil
+			}
+			if err := enc.Encode(e); err != nil {
+				return written, err
+			}
+			written++
+			f.AfterSeq = e.Sequence
+		}
+		if len(page) < verifyPageSize {
+			return written, nil
+		}
+	}
+}
+
+// ComputeHash returns the chain hash of an entry: SHA-256 over the previous
+// hash and the canonical JSON of the entry's contents
+func ComputeHash(e *Entry) (string, error) {
+	content, err := json.Marshal(map[string]interface{}{
+		"sequence":   e.Sequence,
+		"timestamp":  e.Timestamp.UTC().Format(TimestampLayout),
+		"actor":      e.Actor,
+		"action":     e.Action,
+		"resource":   e.Resource,
+		"project_id": e.ProjectID,
+		"outcome":    e.Outcome,
+		"details":    e.Details,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger *Logger
+)
+
+// SetDefault sets the process-wide logger used by Record
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// Default returns the process-wide logger, or nil if none is set
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// Record appends an event to the default logger. It is a no-op when no
+// default is configured, so subsystems can audit unconditionally.
+func Record(ev Event) {
+	l := Default()
+	if l == nil {
+		return
+	}
+	if _, err := l.Record(ev); err != nil {
+		fmt.Printf("Warning: failed to record audit event %s: %v\n", ev.Action, err)
+	}
+}
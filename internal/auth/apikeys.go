@@ -0,0 +1,368 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+const (
+	// apiKeyTokenPrefix marks a bearer credential as an API key rather than
+	// a JWT
+	apiKeyTokenPrefix = "loom_k_"
+
+	// apiKeyDisplayLen is how much of a key is kept in clear for display
+	// and lookup
+	apiKeyDisplayLen = len(apiKeyTokenPrefix) + 8
+
+	// apiKeyLastUsedInterval bounds how often last-used updates are
+	// written to the store, so busy keys do not cost a write per request
+	apiKeyLastUsedInterval = time.Minute
+)
+
+// APIKeyStore persists API keys. Only hashes are stored, never key values.
+type APIKeyStore interface {
+	UpsertAPIKey(k *APIKey) error
+	ListAPIKeys() ([]*APIKey, error)
+}
+
+// SetAPIKeyStore persists API keys to store and loads the keys already in it
+func (m *Manager) SetAPIKeyStore(store APIKeyStore) error {
+	keys, err := store.ListAPIKeys()
+	if err != nil {
+		return fmt.Errorf("failed to load API keys: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiKeyStore = store
+	for _, k := range keys {
+		m.apiKeys[k.ID] = k
+	}
+	log.Printf("Loaded %d API keys", len(keys))
+	return nil
+}
+
+// saveAPIKey writes a key to the store, if there is one. Callers hold m.mu.
+func (m *Manager) saveAPIKey(k *APIKey) error {
+	if m.apiKeyStore == nil {
+		return nil
+	}
+	return m.apiKeyStore.UpsertAPIKey(k)
+}
+
+// CreateAPIKey creates a new API key for a user. The key's permissions are
+// the union of its scopes and explicit permissions, and must all be held
+// by the user.
+func (m *Manager) CreateAPIKey(userID string, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
+	var expiresAt time.Time
+	if req.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	apiKey, keyValue, err := m.newAPIKey(userID, req.Name, req.Scopes, req.Permissions, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.saveAPIKey(apiKey); err != nil {
+		return nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+	m.apiKeys[apiKey.ID] = apiKey
+
+	observability.SecurityAudit("auth.api_key_created", map[string]interface{}{
+		"user_id":     userID,
+		"key_id":      apiKey.ID,
+		"key_prefix":  apiKey.KeyPrefix,
+		"scopes":      apiKey.Scopes,
+		"permissions": apiKey.Permissions,
+	})
+	return newCreateAPIKeyResponse(apiKey, keyValue), nil
+}
+
+// newAPIKey builds a key for a user without registering it. Callers hold m.mu.
+func (m *Manager) newAPIKey(userID, name string, scopes, permissions []string, expiresAt time.Time) (*APIKey, string, error) {
+	user, exists := m.users[userID]
+	if !exists {
+		return nil, "", fmt.Errorf("user not found")
+	}
+	if name == "" {
+		return nil, "", fmt.Errorf("name is required")
+	}
+
+	granted, err := expandAPIKeyScopes(scopes, permissions)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(granted) == 0 {
+		return nil, "", fmt.Errorf("at least one scope or permission is required")
+	}
+	role := m.roles[user.Role]
+	for _, p := range granted {
+		if !matchPermission(role.Permissions, p) {
+			return nil, "", fmt.Errorf("cannot grant %s: not held by %s", p, user.Username)
+		}
+	}
+
+	keyValue := apiKeyTokenPrefix + generateRandomSecret(32)
+	keyHash, err := bcrypt.GenerateFromPassword([]byte(keyValue), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to hash API key: %w", err)
+	}
+
+	return &APIKey{
+		ID:          "key-" + generateRandomSecret(12),
+		Name:        name,
+		UserID:      userID,
+		OrgID:       org.Normalize(user.OrgID),
+		KeyPrefix:   keyValue[:apiKeyDisplayLen],
+		KeyHash:     string(keyHash),
+		Scopes:      scopes,
+		Permissions: granted,
+		IsActive:    true,
+		ExpiresAt:   expiresAt,
+		CreatedAt:   time.Now(),
+	}, keyValue, nil
+}
+
+func newCreateAPIKeyResponse(k *APIKey, keyValue string) *CreateAPIKeyResponse {
+	resp := &CreateAPIKeyResponse{
+		ID:          k.ID,
+		Name:        k.Name,
+		Key:         keyValue, // Only returned once!
+		KeyPrefix:   k.KeyPrefix,
+		Scopes:      k.Scopes,
+		Permissions: k.Permissions,
+	}
+	if !k.ExpiresAt.IsZero() {
+		expiresAt := k.ExpiresAt
+		resp.ExpiresAt = &expiresAt
+	}
+	return resp
+}
+
+// expandAPIKeyScopes resolves scopes to permissions and merges in the
+// explicit permissions, dropping duplicates
+func expandAPIKeyScopes(scopes, permissions []string) ([]string, error) {
+	var granted []string
+	seen := make(map[string]bool)
+	add := func(p string) {
+		if !seen[p] {
+			seen[p] = true
+			granted = append(granted, p)
+		}
+	}
+	for _, name := range scopes {
+		scope, ok := PreDefinedAPIKeyScopes[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown scope: %s", name)
+		}
+		for _, p := range scope.Permissions {
+			add(p)
+		}
+	}
+	for _, p := range permissions {
+		add(p)
+	}
+	return granted, nil
+}
+
+// ValidateAPIKey validates an API key and returns the user and permissions
+func (m *Manager) ValidateAPIKey(keyValue string) (string, []string, error) {
+	if len(keyValue) < apiKeyDisplayLen {
+		return "", nil, fmt.Errorf("invalid API key")
+	}
+	prefix := keyValue[:apiKeyDisplayLen]
+	now := time.Now()
+
+	// Only keys sharing the display prefix need the bcrypt comparison
+	m.mu.RLock()
+	var candidates []*APIKey
+	for _, apiKey := range m.apiKeys {
+		if apiKey.KeyPrefix == prefix && apiKeyUsable(apiKey, now) {
+			candidates = append(candidates, apiKey)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, apiKey := range candidates {
+		if err := bcrypt.CompareHashAndPassword([]byte(apiKey.KeyHash), []byte(keyValue)); err != nil {
+			continue
+		}
+
+		m.mu.Lock()
+		if now.Sub(apiKey.LastUsed) >= apiKeyLastUsedInterval {
+			apiKey.LastUsed = now
+			if err := m.saveAPIKey(apiKey); err != nil {
+				log.Printf("Failed to record API key %s use: %v", apiKey.KeyPrefix, err)
+			}
+		}
+		userID, permissions := apiKey.UserID, apiKey.Permissions
+		m.mu.Unlock()
+
+		return userID, permissions, nil
+	}
+
+	return "", nil, fmt.Errorf("invalid API key")
+}
+
+// apiKeyUsable reports whether a key is active and unexpired
+func apiKeyUsable(k *APIKey, now time.Time) bool {
+	return k.IsActive && (k.ExpiresAt.IsZero() || now.Before(k.ExpiresAt))
+}
+
+// ListAPIKeys returns a user's API keys, or every key when userID is
+// empty, newest first
+func (m *Manager) ListAPIKeys(userID string) []APIKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var keys []APIKey
+	for _, k := range m.apiKeys {
+		if userID == "" || k.UserID == userID {
+			keys = append(keys, *k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	return keys
+}
+
+// GetAPIKey returns a copy of an API key's metadata
+func (m *Manager) GetAPIKey(keyID string) (*APIKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	k, exists := m.apiKeys[keyID]
+	if !exists {
+		return nil, fmt.Errorf("API key not found")
+	}
+	out := *k
+	return &out, nil
+}
+
+// RevokeAPIKey deactivates an API key immediately
+func (m *Manager) RevokeAPIKey(keyID, revokedBy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	k, exists := m.apiKeys[keyID]
+	if !exists {
+		return fmt.Errorf("API key not found")
+	}
+	if !k.IsActive {
+		return nil
+	}
+
+	now := time.Now()
+	k.IsActive = false
+	k.RevokedAt = &now
+	if err := m.saveAPIKey(k); err != nil {
+		return fmt.Errorf("failed to save API key: %w", err)
+	}
+
+	observability.SecurityAudit("auth.api_key_revoked", map[string]interface{}{
+		"actor":      revokedBy,
+		"user_id":    k.UserID,
+		"key_id":     keyID,
+		"key_prefix": k.KeyPrefix,
+	})
+	return nil
+}
+
+// RotateAPIKey issues a replacement for a key with the same name, grants
+// and lifetime. The old key stays valid for gracePeriod so clients can
+// switch without downtime; a zero grace period revokes it at once.
+func (m *Manager) RotateAPIKey(keyID, rotatedBy string, gracePeriod time.Duration) (*CreateAPIKeyResponse, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old, exists := m.apiKeys[keyID]
+	if !exists {
+		return nil, fmt.Errorf("API key not found")
+	}
+	now := time.Now()
+	if !apiKeyUsable(old, now) {
+		return nil, fmt.Errorf("API key is revoked or expired")
+	}
+
+	var expiresAt time.Time
+	if !old.ExpiresAt.IsZero() {
+		expiresAt = now.Add(old.ExpiresAt.Sub(old.CreatedAt))
+	}
+	explicit := old.Permissions
+	if len(old.Scopes) > 0 {
+		explicit = nil
+		fromScopes, _ := expandAPIKeyScopes(old.Scopes, nil)
+		for _, p := range old.Permissions {
+			if !containsString(fromScopes, p) {
+				explicit = append(explicit, p)
+			}
+		}
+	}
+	replacement, keyValue, err := m.newAPIKey(old.UserID, old.Name, old.Scopes, explicit, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	retiring := *old
+	retiring.ReplacedBy = replacement.ID
+	if gracePeriod > 0 {
+		if retireAt := now.Add(gracePeriod); retiring.ExpiresAt.IsZero() || retireAt.Before(retiring.ExpiresAt) {
+			retiring.ExpiresAt = retireAt
+		}
+	} else {
+		retiring.IsActive = false
+		retiring.RevokedAt = &now
+	}
+
+	if err := m.saveAPIKey(replacement); err != nil {
+		return nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+	if err := m.saveAPIKey(&retiring); err != nil {
+		return nil, fmt.Errorf("failed to save API key: %w", err)
+	}
+	m.apiKeys[replacement.ID] = replacement
+	*old = retiring
+
+	observability.SecurityAudit("auth.api_key_rotated", map[string]interface{}{
+		"actor":          rotatedBy,
+		"user_id":        old.UserID,
+		"key_id":         keyID,
+		"new_key_id":     replacement.ID,
+		"new_key_prefix": replacement.KeyPrefix,
+		"grace_period":   gracePeriod.String(),
+	})
+	return newCreateAPIKeyResponse(replacement, keyValue), nil
+}
+
+// ListAPIKeyScopes returns the grantable API key scopes sorted by name
+func ListAPIKeyScopes() []APIKeyScope {
+	scopes := make([]APIKeyScope, 0, len(PreDefinedAPIKeyScopes))
+	for _, s := range PreDefinedAPIKeyScopes {
+		scopes = append(scopes, s)
+	}
+	sort.Slice(scopes, func(i, j int) bool { return scopes[i].Name < scopes[j].Name })
+	return scopes
+}
+
+// isAPIKeyToken reports whether a bearer credential is an API key
+func isAPIKeyToken(token string) bool {
+	return strings.HasPrefix(token, apiKeyTokenPrefix)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
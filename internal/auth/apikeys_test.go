@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateAPIKey_ScopesExpandToPermissions(t *testing.T) {
+	m := NewManager("test-secret")
+
+	resp, err := m.CreateAPIKey("user-admin", CreateAPIKeyRequest{
+		Name:        "reporting",
+		Scopes:      []string{"analytics:read", "beads:create"},
+		Permissions: []string{"beads:read", "agents:read"},
+	})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if !strings.HasPrefix(resp.Key, apiKeyTokenPrefix) || resp.KeyPrefix != resp.Key[:apiKeyDisplayLen] {
+		t.Errorf("unexpected key format: key prefix %q", resp.KeyPrefix)
+	}
+
+	want := []string{"analytics:read", "beads:read", "beads:write", "projects:read", "agents:read"}
+	if strings.Join(resp.Permissions, ",") != strings.Join(want, ",") {
+		t.Errorf("permissions = %v, want %v", resp.Permissions, want)
+	}
+
+	if _, err := m.CreateAPIKey("user-admin", CreateAPIKeyRequest{Name: "bad", Scopes: []string{"root"}}); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+	if _, err := m.CreateAPIKey("user-admin", CreateAPIKeyRequest{Name: "empty"}); err == nil {
+		t.Error("expected error for key without grants")
+	}
+}
+
+func TestCreateAPIKey_CannotExceedOwnerRole(t *testing.T) {
+	m := NewManager("test-secret")
+	viewer, _ := m.CreateUser("vera", "vera@test.com", "viewer", "pw")
+
+	if _, err := m.CreateAPIKey(viewer.ID, CreateAPIKeyRequest{Name: "dash", Scopes: []string{"analytics:read"}}); err != nil {
+		t.Errorf("viewer should be able to mint an analytics key: %v", err)
+	}
+	if _, err := m.CreateAPIKey(viewer.ID, CreateAPIKeyRequest{Name: "escalate", Scopes: []string{"admin"}}); err == nil {
+		t.Error("viewer must not mint an admin key")
+	}
+	if _, err := m.CreateAPIKey(viewer.ID, CreateAPIKeyRequest{Name: "writer", Scopes: []string{"beads:create"}}); err == nil {
+		t.Error("viewer must not mint a bead creation key")
+	}
+}
+
+func TestRotateAPIKey_GracePeriod(t *testing.T) {
+	m := NewManager("test-secret")
+	orig, err := m.CreateAPIKey("user-admin", CreateAPIKeyRequest{Name: "ci", Scopes: []string{"beads:create"}, ExpiresIn: 3600})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	rotated, err := m.RotateAPIKey(orig.ID, "user-admin", time.Hour)
+	if err != nil {
+		t.Fatalf("RotateAPIKey failed: %v", err)
+	}
+	if rotated.ID == orig.ID || rotated.Key == orig.Key || rotated.Name != "ci" {
+		t.Errorf("unexpected replacement: %+v", rotated)
+	}
+	if strings.Join(rotated.Scopes, ",") != "beads:create" || rotated.ExpiresAt == nil {
+		t.Errorf("replacement should keep scopes and lifetime: %+v", rotated)
+	}
+
+	// Both keys work during the grace period
+	if _, _, err := m.ValidateAPIKey(orig.Key); err != nil {
+		t.Errorf("old key should work during grace period: %v", err)
+	}
+	if _, _, err := m.ValidateAPIKey(rotated.Key); err != nil {
+		t.Errorf("new key should work: %v", err)
+	}
+	old, _ := m.GetAPIKey(orig.ID)
+	if old.ReplacedBy != rotated.ID {
+		t.Errorf("ReplacedBy = %q, want %q", old.ReplacedBy, rotated.ID)
+	}
+
+	// Without a grace period the old key stops at once
+	again, err := m.RotateAPIKey(rotated.ID, "user-admin", 0)
+	if err != nil {
+		t.Fatalf("RotateAPIKey failed: %v", err)
+	}
+	if _, _, err := m.ValidateAPIKey(rotated.Key); err == nil {
+		t.Error("old key should be revoked immediately without grace period")
+	}
+	if _, _, err := m.ValidateAPIKey(again.Key); err != nil {
+		t.Errorf("new key should work: %v", err)
+	}
+	if _, err := m.RotateAPIKey(rotated.ID, "user-admin", 0); err == nil {
+		t.Error("expected error rotating a revoked key")
+	}
+}
+
+func TestRevokeAPIKey_AndListByOwner(t *testing.T) {
+	m := NewManager("test-secret")
+	user, _ := m.CreateUser("ursula", "ursula@test.com", "user", "pw")
+
+	mine, _ := m.CreateAPIKey(user.ID, CreateAPIKeyRequest{Name: "mine", Scopes: []string{"beads:create"}})
+	if _, err := m.CreateAPIKey("user-admin", CreateAPIKeyRequest{Name: "admin", Scopes: []string{"admin"}}); err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+
+	if keys := m.ListAPIKeys(user.ID); len(keys) != 1 || keys[0].ID != mine.ID {
+		t.Errorf("expected only the user's key, got %+v", keys)
+	}
+	if keys := m.ListAPIKeys(""); len(keys) != 2 {
+		t.Errorf("expected 2 keys in total, got %d", len(keys))
+	}
+
+	if err := m.RevokeAPIKey(mine.ID, user.ID); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+	if _, _, err := m.ValidateAPIKey(mine.Key); err == nil {
+		t.Error("revoked key should not validate")
+	}
+	if err := m.RevokeAPIKey("key-missing", user.ID); err == nil {
+		t.Error("expected error for unknown key")
+	}
+}
+
+func TestValidateAPIKey_TracksLastUsed(t *testing.T) {
+	m := NewManager("test-secret")
+	resp, _ := m.CreateAPIKey("user-admin", CreateAPIKeyRequest{Name: "ci", Scopes: []string{"analytics:read"}})
+
+	if k, _ := m.GetAPIKey(resp.ID); !k.LastUsed.IsZero() {
+		t.Error("new key should not have a last-used time")
+	}
+	if _, _, err := m.ValidateAPIKey(resp.Key); err != nil {
+		t.Fatalf("ValidateAPIKey failed: %v", err)
+	}
+	if k, _ := m.GetAPIKey(resp.ID); k.LastUsed.IsZero() {
+		t.Error("expected last-used time to be recorded")
+	}
+}
+
+func TestRequirePermission_APIKeyScopes(t *testing.T) {
+	m := NewManager("test-secret")
+	user, _ := m.CreateUser("ci", "ci@test.com", "user", "pw")
+	resp, _ := m.CreateAPIKey(user.ID, CreateAPIKeyRequest{Name: "analytics", Scopes: []string{"analytics:read"}})
+
+	handler := m.Middleware("analytics:read")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAPIKeyRequest(r) {
+			t.Error("expected request to be marked as API key authenticated")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	for _, header := range []string{"X-API-Key", "Authorization"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/analytics/costs", nil)
+		if header == "Authorization" {
+			req.Header.Set(header, "Bearer "+resp.Key)
+		} else {
+			req.Header.Set(header, resp.Key)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d", header, rec.Code)
+		}
+	}
+
+	denied := m.Middleware("beads:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/beads", nil)
+	req.Header.Set("X-API-Key", resp.Key)
+	rec := httptest.NewRecorder()
+	denied.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 outside key scope, got %d", rec.Code)
+	}
+
+	// Deactivating the owner disables their keys
+	m.users[user.ID].IsActive = false
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/analytics/costs", nil)
+	req.Header.Set("X-API-Key", resp.Key)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for deactivated owner, got %d", rec.Code)
+	}
+}
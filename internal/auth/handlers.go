@@ -1,8 +1,14 @@
 package auth
 
 import (
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
 // Handlers provides HTTP handlers for auth operations
@@ -82,6 +88,10 @@ func (h *Handlers) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
+	if IsAPIKeyRequest(r) {
+		http.Error(w, "API keys cannot manage API keys", http.StatusForbidden)
+		return
+	}
 
 	var req CreateAPIKeyRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -102,6 +112,226 @@ func (h *Handlers) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// HandleListAPIKeys handles GET /auth/api-keys. Admins may pass ?all=true
+// to see every user's keys in their organization.
+func (h *Handlers) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID := GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	owner := userID
+	if r.URL.Query().Get("all") == "true" {
+		if GetRoleFromRequest(r) != "admin" {
+			http.Error(w, "Admin access required", http.StatusForbidden)
+			return
+		}
+		owner = ""
+	}
+
+	keys := h.manager.ListAPIKeys(owner)
+	if scope := GetOrgIDFromRequest(r); scope != "" {
+		inOrg := keys[:0]
+		for _, k := range keys {
+			if org.Normalize(k.OrgID) == scope {
+				inOrg = append(inOrg, k)
+			}
+		}
+		keys = inOrg
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"api_keys": keys,
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// HandleAPIKey handles /auth/api-keys/{id}: DELETE revokes the key and
+// POST with action "rotate" issues a replacement. Keys can be managed by
+// their owner or an admin of the key's organization.
+func (h *Handlers) HandleAPIKey(w http.ResponseWriter, r *http.Request, keyID, action string) {
+	userID := GetUserIDFromRequest(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if IsAPIKeyRequest(r) {
+		http.Error(w, "API keys cannot manage API keys", http.StatusForbidden)
+		return
+	}
+
+	key, err := h.manager.GetAPIKey(keyID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if key.UserID != userID && (GetRoleFromRequest(r) != "admin" || !inCallerOrg(r, key.OrgID)) {
+		// Do not reveal other users' key IDs
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(key); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	case action == "" && r.Method == http.MethodDelete:
+		if err := h.manager.RevokeAPIKey(keyID, userID); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case action == "rotate" && r.Method == http.MethodPost:
+		var req RotateAPIKeyRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.GracePeriod < 0 {
+			http.Error(w, "grace_period must not be negative", http.StatusBadRequest)
+			return
+		}
+		resp, err := h.manager.RotateAPIKey(keyID, userID, time.Duration(req.GracePeriod)*time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		}
+	case action != "" && action != "rotate":
+		http.Error(w, "Not found", http.StatusNotFound)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// HandleOIDCInfo handles GET /auth/oidc (no auth required): tells the login
+// page whether SSO is available
+func (h *Handlers) HandleOIDCInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	info := map[string]interface{}{"enabled": false}
+	if provider := h.manager.OIDC(); provider != nil {
+		info = map[string]interface{}{
+			"enabled":   true,
+			"provider":  provider.Name(),
+			"login_url": "/api/v1/auth/oidc/login",
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(info); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// HandleOIDCLogin handles GET /auth/oidc/login: redirects the browser to
+// the identity provider. The login's state is also set in a short-lived
+// cookie, so only the browser that started a login can complete it.
+func (h *Handlers) HandleOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	provider := h.manager.OIDC()
+	if provider == nil {
+		http.Error(w, "SSO is not enabled", http.StatusNotFound)
+		return
+	}
+	authURL, state, err := provider.AuthCodeURL(r.Context())
+	if errors.Is(err, errOIDCBusy) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	setOIDCStateCookie(w, provider, state, int(oidcLoginTTL.Seconds()))
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// HandleOIDCCallback handles GET /auth/oidc/callback: completes the login
+// and sends the browser back to the UI with its token in the URL fragment,
+// which is never sent to servers or written to access logs
+func (h *Handlers) HandleOIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	provider := h.manager.OIDC()
+	if provider == nil {
+		http.Error(w, "SSO is not enabled", http.StatusNotFound)
+		return
+	}
+
+	q := r.URL.Query()
+	if idpErr := q.Get("error"); idpErr != "" {
+		http.Error(w, "SSO login failed: "+idpErr+" "+q.Get("error_description"), http.StatusUnauthorized)
+		return
+	}
+	// The IdP redirect carries the state; the cookie proves this browser
+	// asked for it, so an attacker cannot log a victim into their account
+	cookie, err := r.Cookie(oidcStateCookie)
+	setOIDCStateCookie(w, provider, "", -1)
+	if err != nil || q.Get("state") == "" ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(q.Get("state"))) != 1 {
+		http.Error(w, "SSO login failed: login was not started by this browser", http.StatusUnauthorized)
+		return
+	}
+	resp, err := h.manager.LoginOIDC(r.Context(), q.Get("state"), q.Get("code"))
+	if err != nil {
+		http.Error(w, "SSO login failed: "+err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, provider.cfg.PostLoginRedirect+"#sso_token="+url.QueryEscape(resp.Token), http.StatusFound)
+}
+
+// setOIDCStateCookie sets, or with a negative maxAge clears, the cookie
+// holding a login's state. It is scoped to the callback path and sent on
+// the IdP's top-level redirect back, which SameSite=Lax allows.
+func setOIDCStateCookie(w http.ResponseWriter, provider *OIDCProvider, state string, maxAge int) {
+	path := "/api/v1/auth/oidc/callback"
+	secure := true
+	if u, err := url.Parse(provider.cfg.RedirectURL); err == nil {
+		if u.Path != "" {
+			path = u.Path
+		}
+		secure = u.Scheme != "http"
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     path,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
 // HandleGetCurrentUser handles GET /auth/me
 func (h *Handlers) HandleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -127,7 +357,8 @@ func (h *Handlers) HandleGetCurrentUser(w http.ResponseWriter, r *http.Request)
 	}
 }
 
-// HandleCreateUser handles POST /auth/users (admin only)
+// HandleCreateUser handles POST /auth/users (admin only). Admins confined
+// to an organization create users in it.
 func (h *Handlers) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -146,14 +377,22 @@ func (h *Handlers) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 		Email    string `json:"email"`
 		Role     string `json:"role"`
 		Password string `json:"password"`
+		OrgID    string `json:"org_id"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	if scope := GetOrgIDFromRequest(r); scope != "" {
+		if req.OrgID != "" && req.OrgID != scope {
+			http.Error(w, "Cannot create users in another organization", http.StatusForbidden)
+			return
+		}
+		req.OrgID = scope
+	}
 
-	user, err := h.manager.CreateUser(req.Username, req.Email, req.Role, req.Password)
+	user, err := h.manager.CreateUserInOrg(req.OrgID, req.Username, req.Email, req.Role, req.Password)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -166,7 +405,8 @@ func (h *Handlers) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// HandleListUsers handles GET /auth/users (admin only)
+// HandleListUsers handles GET /auth/users (admin only), limited to the
+// caller's organization when they are confined to one
 func (h *Handlers) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -181,6 +421,9 @@ func (h *Handlers) HandleListUsers(w http.ResponseWriter, r *http.Request) {
 	}
 
 	users := h.manager.ListUsers()
+	if scope := GetOrgIDFromRequest(r); scope != "" {
+		users = h.manager.ListUsersInOrg(scope)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(users); err != nil {
@@ -234,3 +477,93 @@ func (h *Handlers) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
 	}
 }
+
+// HandleListRoles handles GET /auth/roles
+func (h *Handlers) HandleListRoles(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"roles":          h.manager.ListRoles(),
+		"permissions":    PreDefinedPermissions,
+		"api_key_scopes": ListAPIKeyScopes(),
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// HandleUserRoles handles /auth/users/{id}/roles (admin only):
+// GET lists the user's global role and project bindings, PUT assigns a
+// role, DELETE ?project_id= revokes a project binding
+func (h *Handlers) HandleUserRoles(w http.ResponseWriter, r *http.Request, userID string) {
+	// Check admin permission
+	role := GetRoleFromRequest(r)
+	if role != "admin" {
+		http.Error(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	user, err := h.manager.GetUser(userID)
+	if err != nil || !inCallerOrg(r, user.OrgID) {
+		http.Error(w, "user not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var req AssignRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.ProjectID == "" && userID == GetUserIDFromRequest(r) && req.Role != "admin" {
+			http.Error(w, "Admins cannot remove their own admin role", http.StatusBadRequest)
+			return
+		}
+		if req.ProjectID != "" && !h.manager.projectInOrg(req.ProjectID, org.Normalize(user.OrgID)) {
+			http.Error(w, "Project belongs to another organization", http.StatusBadRequest)
+			return
+		}
+		if _, err := h.manager.AssignRole(userID, req.Role, req.ProjectID, GetUserIDFromRequest(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		projectID := r.URL.Query().Get("project_id")
+		if projectID == "" {
+			http.Error(w, "project_id is required", http.StatusBadRequest)
+			return
+		}
+		if err := h.manager.RevokeProjectRole(userID, projectID, GetUserIDFromRequest(r)); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.manager.mu.RLock()
+	globalRole := user.Role
+	h.manager.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":       userID,
+		"role":          globalRole,
+		"project_roles": h.manager.ListRoleBindings(userID),
+	}); err != nil {
+		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+	}
+}
+
+// inCallerOrg reports whether a record of organization orgID is visible to
+// the caller
+func inCallerOrg(r *http.Request, orgID string) bool {
+	scope := GetOrgIDFromRequest(r)
+	return scope == "" || org.Normalize(orgID) == scope
+}
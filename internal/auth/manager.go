@@ -4,22 +4,32 @@ import (
 	"crypto/rand"
 	"fmt"
 	"log"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
 // Manager handles authentication and authorization
 type Manager struct {
 	jwtSecret string
-	users     map[string]*User   // userID -> User
-	tokens    map[string]*Token  // tokenID -> Token
-	apiKeys   map[string]*APIKey // keyID -> APIKey
-	passwords map[string]string  // userID -> password hash
-	roles     map[string]Role    // roleName -> Role
+	users     map[string]*User         // userID -> User
+	tokens    map[string]*Token        // tokenID -> Token
+	apiKeys   map[string]*APIKey       // keyID -> APIKey
+	passwords map[string]string        // userID -> password hash
+	roles     map[string]Role          // roleName -> Role
+	bindings  map[string][]RoleBinding // userID -> project-scoped roles
 	tokenTTL  time.Duration
+	mu        sync.RWMutex // guards role assignments and API keys
+
+	apiKeyStore APIKeyStore   // optional; keys live only in memory without it
+	oidc        *OIDCProvider // optional; set when SSO is enabled
+
+	orgs       *org.Manager                  // optional; only the default organization exists without it
+	projectOrg func(projectID string) string // optional; see SetProjectOrgResolver
 }
 
 // NewManager creates a new auth manager
@@ -37,6 +47,7 @@ func NewManager(jwtSecret string) *Manager {
 		apiKeys:   make(map[string]*APIKey),
 		passwords: make(map[string]string),
 		roles:     make(map[string]Role),
+		bindings:  make(map[string][]RoleBinding),
 		tokenTTL:  24 * time.Hour,
 	}
 
@@ -51,6 +62,7 @@ func NewManager(jwtSecret string) *Manager {
 		Username:  "admin",
 		Email:     "admin@loom.local",
 		Role:      "admin",
+		OrgID:     org.DefaultID,
 		IsActive:  true,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -117,6 +129,7 @@ func (m *Manager) GenerateToken(user *User) (string, error) {
 		UserID:      user.ID,
 		Username:    user.Username,
 		Role:        user.Role,
+		OrgID:       user.OrgID,
 		Permissions: role.Permissions,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
@@ -171,78 +184,6 @@ func (m *Manager) ValidateToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// CreateAPIKey creates a new API key for a user
-func (m *Manager) CreateAPIKey(userID string, req CreateAPIKeyRequest) (*CreateAPIKeyResponse, error) {
-	user, exists := m.users[userID]
-	if !exists {
-		return nil, fmt.Errorf("user not found")
-	}
-
-	// Generate API key
-	keyID := generateRandomID()
-	keyValue := generateRandomSecret(32)
-	keyPrefix := keyValue[:8]
-	keyHash, _ := bcrypt.GenerateFromPassword([]byte(keyValue), bcrypt.DefaultCost)
-
-	var expiresAt *time.Time
-	var expiresAtValue time.Time
-	if req.ExpiresIn > 0 {
-		exp := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
-		expiresAt = &exp
-		expiresAtValue = exp
-	}
-
-	apiKey := &APIKey{
-		ID:          keyID,
-		Name:        req.Name,
-		UserID:      userID,
-		KeyPrefix:   keyPrefix,
-		KeyHash:     string(keyHash),
-		Permissions: req.Permissions,
-		IsActive:    true,
-		ExpiresAt:   expiresAtValue,
-		CreatedAt:   time.Now(),
-	}
-
-	m.apiKeys[keyID] = apiKey
-
-	log.Printf("Created API key %s for user %s", keyPrefix, user.Username)
-
-	return &CreateAPIKeyResponse{
-		ID:        keyID,
-		Name:      req.Name,
-		Key:       keyValue, // Only returned once!
-		ExpiresAt: expiresAt,
-	}, nil
-}
-
-// ValidateAPIKey validates an API key and returns the user and permissions
-func (m *Manager) ValidateAPIKey(keyValue string) (string, []string, error) {
-	// Find API key by hashing the provided value
-	for _, apiKey := range m.apiKeys {
-		if !apiKey.IsActive {
-			continue
-		}
-
-		// Check expiration
-		if apiKey.ExpiresAt != *new(time.Time) && time.Now().After(apiKey.ExpiresAt) {
-			continue
-		}
-
-		// Verify key hash
-		if err := bcrypt.CompareHashAndPassword([]byte(apiKey.KeyHash), []byte(keyValue)); err != nil {
-			continue
-		}
-
-		// Update last used
-		apiKey.LastUsed = time.Now()
-
-		return apiKey.UserID, apiKey.Permissions, nil
-	}
-
-	return "", nil, fmt.Errorf("invalid API key")
-}
-
 // ChangePassword changes a user's password
 func (m *Manager) ChangePassword(userID, oldPassword, newPassword string) error {
 	user, exists := m.users[userID]
@@ -273,8 +214,18 @@ func (m *Manager) ChangePassword(userID, oldPassword, newPassword string) error
 	return nil
 }
 
-// CreateUser creates a new user
+// CreateUser creates a new user in the default organization
 func (m *Manager) CreateUser(username, email, role, password string) (*User, error) {
+	return m.CreateUserInOrg(org.DefaultID, username, email, role, password)
+}
+
+// CreateUserInOrg creates a new user in an organization
+func (m *Manager) CreateUserInOrg(orgID, username, email, role, password string) (*User, error) {
+	orgID = org.Normalize(orgID)
+	if !m.orgs.Exists(orgID) {
+		return nil, fmt.Errorf("unknown organization: %s", orgID)
+	}
+
 	// Check if username already exists
 	for _, u := range m.users {
 		if u.Username == username {
@@ -293,6 +244,7 @@ func (m *Manager) CreateUser(username, email, role, password string) (*User, err
 		Username:  username,
 		Email:     email,
 		Role:      role,
+		OrgID:     orgID,
 		IsActive:  true,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
@@ -304,7 +256,7 @@ func (m *Manager) CreateUser(username, email, role, password string) (*User, err
 
 	m.users[userID] = user
 
-	log.Printf("Created user %s with role %s", username, role)
+	log.Printf("Created user %s with role %s in organization %s", username, role, orgID)
 	return user, nil
 }
 
@@ -326,28 +278,37 @@ func (m *Manager) ListUsers() []*User {
 	return users
 }
 
-// HasPermission checks if a user has a permission
-func (m *Manager) HasPermission(claims *Claims, permission string) bool {
-	for _, p := range claims.Permissions {
-		// Check for exact match
-		if p == permission {
-			return true
-		}
-		// Check for wildcard
-		if p == "*:*" {
-			return true
-		}
-		// Check for resource wildcard (e.g., "agents:*")
-		// Extract resource part (before :) and check if permission matches with wildcard
-		parts := strings.Split(permission, ":")
-		if len(parts) == 2 {
-			resourceWildcard := parts[0] + ":*"
-			if p == resourceWildcard {
-				return true
-			}
+// ListUsersInOrg lists the users of one organization
+func (m *Manager) ListUsersInOrg(orgID string) []*User {
+	orgID = org.Normalize(orgID)
+	var users []*User
+	for _, u := range m.users {
+		if org.Normalize(u.OrgID) == orgID {
+			users = append(users, u)
 		}
 	}
-	return false
+	return users
+}
+
+// SetOrgs sets the organizations users may be created in
+func (m *Manager) SetOrgs(orgs *org.Manager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orgs = orgs
+}
+
+// SetProjectOrgResolver sets how the organization owning a project is
+// found, so callers confined to an organization are refused other
+// organizations' projects. resolve returns "" for unknown projects.
+func (m *Manager) SetProjectOrgResolver(resolve func(projectID string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.projectOrg = resolve
+}
+
+// HasPermission checks if a user has a permission
+func (m *Manager) HasPermission(claims *Claims, permission string) bool {
+	return matchPermission(claims.Permissions, permission)
 }
 
 // generateRandomID generates a random ID
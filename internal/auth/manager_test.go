@@ -555,7 +555,7 @@ func TestManager_TokenTTL(t *testing.T) {
 func TestManager_PreDefinedRoles(t *testing.T) {
 	m := NewManager("test-secret")
 
-	expectedRoles := []string{"admin", "user", "viewer", "service"}
+	expectedRoles := []string{"admin", "operator", "user", "viewer", "service"}
 
 	for _, roleName := range expectedRoles {
 		role, exists := m.roles[roleName]
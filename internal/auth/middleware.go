@@ -4,80 +4,114 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
 // Middleware wraps an HTTP handler with authentication
 func (m *Manager) Middleware(requiredPermission string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Get token from Authorization header
-			authHeader := r.Header.Get("Authorization")
-			if authHeader == "" {
-				// Try API key auth
-				apiKey := r.Header.Get("X-API-Key")
-				if apiKey == "" {
-					http.Error(w, "Missing authorization header", http.StatusUnauthorized)
-					return
-				}
-
-				// Validate API key
-				userID, permissions, err := m.ValidateAPIKey(apiKey)
-				if err != nil {
-					http.Error(w, "Invalid API key", http.StatusUnauthorized)
-					return
-				}
+	return m.RequirePermission(func(*http.Request) (string, string) {
+		return requiredPermission, ""
+	})
+}
 
-				// Check permission
-				if requiredPermission != "" {
-					hasPermission := false
-					for _, p := range permissions {
-						if p == requiredPermission || p == "*:*" {
-							hasPermission = true
-							break
-						}
-					}
-					if !hasPermission {
-						http.Error(w, "Insufficient permissions", http.StatusForbidden)
-						return
-					}
-				}
+// PermissionPolicy returns the permission a request needs and the project
+// it targets ("" when it is not project-specific)
+type PermissionPolicy func(r *http.Request) (permission, projectID string)
 
-				// Store userID in context
-				r.Header.Set("X-User-ID", userID)
-				next.ServeHTTP(w, r)
+// RequirePermission authenticates each request and authorizes it against
+// the permission chosen by policy. Project-scoped role bindings apply when
+// the policy identifies the request's project.
+func (m *Manager) RequirePermission(policy PermissionPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, status, msg := m.authenticate(r)
+			if principal == nil {
+				http.Error(w, msg, status)
 				return
 			}
 
-			// Extract token from "Bearer <token>" format
-			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
-				http.Error(w, "Invalid authorization header format", http.StatusUnauthorized)
+			permission, projectID := policy(r)
+			if !m.Authorize(principal, permission, projectID) {
+				http.Error(w, "Insufficient permissions", http.StatusForbidden)
 				return
 			}
 
-			tokenString := parts[1]
+			// Store identity in headers for downstream handlers
+			principal.Role, _ = m.currentRole(principal)
+			principal.OrgID = m.currentOrg(principal)
+			setIdentityHeaders(r, principal)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-			// Validate token
-			claims, err := m.ValidateToken(tokenString)
-			if err != nil {
-				http.Error(w, fmt.Sprintf("Invalid token: %v", err), http.StatusUnauthorized)
-				return
-			}
+// authenticate resolves the caller from a bearer token or API key. On
+// failure it returns the HTTP status and message to send.
+func (m *Manager) authenticate(r *http.Request) (*Principal, int, string) {
+	// Never trust identity headers supplied by the client
+	clearIdentityHeaders(r)
+
+	// Get token from Authorization header
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		// Try API key auth
+		apiKey := r.Header.Get("X-API-Key")
+		if apiKey == "" {
+			return nil, http.StatusUnauthorized, "Missing authorization header"
+		}
+
+		userID, permissions, err := m.ValidateAPIKey(apiKey)
+		if err != nil {
+			return nil, http.StatusUnauthorized, "Invalid API key"
+		}
+		return &Principal{UserID: userID, APIKeyPermissions: permissions, IsAPIKey: true}, 0, ""
+	}
 
-			// Check permission
-			if requiredPermission != "" && !m.HasPermission(claims, requiredPermission) {
-				http.Error(w, "Insufficient permissions", http.StatusForbidden)
-				return
-			}
+	// Extract token from "Bearer <token>" format
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, http.StatusUnauthorized, "Invalid authorization header format"
+	}
 
-			// Store claims in header for downstream handlers
-			r.Header.Set("X-User-ID", claims.UserID)
-			r.Header.Set("X-Username", claims.Username)
-			r.Header.Set("X-Role", claims.Role)
+	// API keys may also be sent as bearer credentials
+	if isAPIKeyToken(parts[1]) {
+		userID, permissions, err := m.ValidateAPIKey(parts[1])
+		if err != nil {
+			return nil, http.StatusUnauthorized, "Invalid API key"
+		}
+		return &Principal{UserID: userID, APIKeyPermissions: permissions, IsAPIKey: true}, 0, ""
+	}
 
-			next.ServeHTTP(w, r)
-		})
+	claims, err := m.ValidateToken(parts[1])
+	if err != nil {
+		return nil, http.StatusUnauthorized, fmt.Sprintf("Invalid token: %v", err)
 	}
+	return &Principal{UserID: claims.UserID, Username: claims.Username, Role: claims.Role, OrgID: claims.OrgID}, 0, ""
+}
+
+// setIdentityHeaders publishes the principal to downstream handlers. The
+// role is the user's current global role, which may differ from the token.
+// X-Org-ID is only set for callers confined to an organization.
+func setIdentityHeaders(r *http.Request, p *Principal) {
+	r.Header.Set("X-User-ID", p.UserID)
+	if org.Confined(p.OrgID) {
+		r.Header.Set("X-Org-ID", p.OrgID)
+	}
+	if p.IsAPIKey {
+		r.Header.Set("X-Auth-Type", "api_key")
+		return
+	}
+	r.Header.Set("X-Username", p.Username)
+	r.Header.Set("X-Role", p.Role)
+}
+
+func clearIdentityHeaders(r *http.Request) {
+	r.Header.Del("X-User-ID")
+	r.Header.Del("X-Username")
+	r.Header.Del("X-Role")
+	r.Header.Del("X-Auth-Type")
+	r.Header.Del("X-Org-ID")
 }
 
 // OptionalAuth wraps a handler with optional authentication
@@ -85,13 +119,16 @@ func (m *Manager) Middleware(requiredPermission string) func(http.Handler) http.
 func (m *Manager) OptionalAuth() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			clearIdentityHeaders(r)
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
 				// Try API key
 				apiKey := r.Header.Get("X-API-Key")
 				if apiKey != "" {
 					if userID, _, err := m.ValidateAPIKey(apiKey); err == nil {
-						r.Header.Set("X-User-ID", userID)
+						p := &Principal{UserID: userID, IsAPIKey: true}
+						p.OrgID = m.currentOrg(p)
+						setIdentityHeaders(r, p)
 					}
 				}
 				next.ServeHTTP(w, r)
@@ -100,12 +137,18 @@ func (m *Manager) OptionalAuth() func(http.Handler) http.Handler {
 
 			// Extract token
 			parts := strings.Split(authHeader, " ")
-			if len(parts) == 2 && parts[0] == "Bearer" {
+			if len(parts) == 2 && parts[0] == "Bearer" && isAPIKeyToken(parts[1]) {
+				if userID, _, err := m.ValidateAPIKey(parts[1]); err == nil {
+					p := &Principal{UserID: userID, IsAPIKey: true}
+					p.OrgID = m.currentOrg(p)
+					setIdentityHeaders(r, p)
+				}
+			} else if len(parts) == 2 && parts[0] == "Bearer" {
 				tokenString := parts[1]
 				if claims, err := m.ValidateToken(tokenString); err == nil {
-					r.Header.Set("X-User-ID", claims.UserID)
-					r.Header.Set("X-Username", claims.Username)
-					r.Header.Set("X-Role", claims.Role)
+					p := &Principal{UserID: claims.UserID, Username: claims.Username, Role: claims.Role, OrgID: claims.OrgID}
+					p.OrgID = m.currentOrg(p)
+					setIdentityHeaders(r, p)
 				}
 			}
 
@@ -128,3 +171,15 @@ func GetUsernameFromRequest(r *http.Request) string {
 func GetRoleFromRequest(r *http.Request) string {
 	return r.Header.Get("X-Role")
 }
+
+// GetOrgIDFromRequest returns the organization the caller is confined to,
+// or "" when the caller may see every organization
+func GetOrgIDFromRequest(r *http.Request) string {
+	return r.Header.Get("X-Org-ID")
+}
+
+// IsAPIKeyRequest reports whether the request was authenticated with an
+// API key rather than a session token
+func IsAPIKeyRequest(r *http.Request) bool {
+	return r.Header.Get("X-Auth-Type") == "api_key"
+}
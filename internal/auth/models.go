@@ -12,9 +12,15 @@ type User struct {
 	Username  string    `json:"username"`
 	Email     string    `json:"email,omitempty"`
 	Role      string    `json:"role"` // admin, user, viewer, service
+	OrgID     string    `json:"org_id"`
 	IsActive  bool      `json:"is_active"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+
+	// AuthProvider and ExternalID link users provisioned by SSO to their
+	// identity: the OIDC issuer and subject. Local users leave them empty.
+	AuthProvider string `json:"auth_provider,omitempty"`
+	ExternalID   string `json:"external_id,omitempty"`
 }
 
 // Token represents an authentication token
@@ -29,16 +35,28 @@ type Token struct {
 
 // APIKey represents a service account API key
 type APIKey struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	UserID      string    `json:"user_id"`
-	KeyPrefix   string    `json:"key_prefix"` // First 8 chars for display
-	KeyHash     string    `json:"-"`          // Never send to client
-	Permissions []string  `json:"permissions"`
-	IsActive    bool      `json:"is_active"`
-	ExpiresAt   time.Time `json:"expires_at,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	LastUsed    time.Time `json:"last_used,omitempty"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	UserID      string     `json:"user_id"`
+	OrgID       string     `json:"org_id"`     // The owner's organization when the key was created
+	KeyPrefix   string     `json:"key_prefix"` // "loom_k_" plus 8 chars for display
+	KeyHash     string     `json:"-"`          // Never send to client
+	Scopes      []string   `json:"scopes,omitempty"`
+	Permissions []string   `json:"permissions"` // Scope grants plus explicit permissions
+	IsActive    bool       `json:"is_active"`
+	ExpiresAt   time.Time  `json:"expires_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsed    time.Time  `json:"last_used,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+	ReplacedBy  string     `json:"replaced_by,omitempty"` // Key that superseded this one on rotation
+}
+
+// APIKeyScope is a named bundle of permissions that can be granted to an
+// API key
+type APIKeyScope struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
 }
 
 // Role defines permissions for users
@@ -56,11 +74,29 @@ type Permission struct {
 	Action      string `json:"action"`   // read, write, delete, admin
 }
 
+// RoleBinding grants a user a role within one project, in addition to the
+// user's global role
+type RoleBinding struct {
+	UserID    string    `json:"user_id"`
+	Role      string    `json:"role"`
+	ProjectID string    `json:"project_id"`
+	GrantedBy string    `json:"granted_by,omitempty"`
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// AssignRoleRequest assigns a global role (no project_id) or a
+// project-scoped role
+type AssignRoleRequest struct {
+	Role      string `json:"role"`
+	ProjectID string `json:"project_id,omitempty"`
+}
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID      string   `json:"user_id"`
 	Username    string   `json:"username"`
 	Role        string   `json:"role"`
+	OrgID       string   `json:"org_id,omitempty"`
 	Permissions []string `json:"permissions"`
 	jwt.RegisteredClaims
 }
@@ -111,16 +147,26 @@ type RefreshTokenRequest struct {
 // CreateAPIKeyRequest represents API key creation request
 type CreateAPIKeyRequest struct {
 	Name        string   `json:"name"`
+	Scopes      []string `json:"scopes,omitempty"` // see PreDefinedAPIKeyScopes
 	Permissions []string `json:"permissions"`
 	ExpiresIn   int64    `json:"expires_in,omitempty"` // seconds, 0 = no expiry
 }
 
 // CreateAPIKeyResponse returns the new API key (only shown once)
 type CreateAPIKeyResponse struct {
-	ID        string     `json:"id"`
-	Name      string     `json:"name"`
-	Key       string     `json:"key"` // Full key - only shown once!
-	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Key         string     `json:"key"` // Full key - only shown once!
+	KeyPrefix   string     `json:"key_prefix,omitempty"`
+	Scopes      []string   `json:"scopes,omitempty"`
+	Permissions []string   `json:"permissions,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// RotateAPIKeyRequest replaces a key. The old key keeps working for
+// GracePeriod seconds so clients can switch over; 0 revokes it at once.
+type RotateAPIKeyRequest struct {
+	GracePeriod int64 `json:"grace_period,omitempty"`
 }
 
 // ChangePasswordRequest represents a password change request
@@ -138,6 +184,22 @@ var PreDefinedRoles = map[string]Role{
 			"*:*", // All permissions
 		},
 	},
+	"operator": {
+		Name:        "operator",
+		Description: "Runs the system day to day: full access to work resources, no user or security administration",
+		Permissions: []string{
+			"agents:*",
+			"analytics:read",
+			"beads:*",
+			"providers:*",
+			"projects:*",
+			"decisions:*",
+			"repl:use",
+			"config:read",
+			"system:read",
+			"system:write",
+		},
+	},
 	"user": {
 		Name:        "user",
 		Description: "Read and write access to most resources",
@@ -152,7 +214,9 @@ var PreDefinedRoles = map[string]Role{
 			"projects:write",
 			"decisions:read",
 			"decisions:write",
+			"analytics:read",
 			"repl:use",
+			"system:read",
 		},
 	},
 	"viewer": {
@@ -164,6 +228,8 @@ var PreDefinedRoles = map[string]Role{
 			"providers:read",
 			"projects:read",
 			"decisions:read",
+			"analytics:read",
+			"system:read",
 		},
 	},
 	"service": {
@@ -208,10 +274,42 @@ var PreDefinedPermissions = []Permission{
 	{Name: "decisions:delete", Resource: "decisions", Action: "delete", Description: "Delete decisions"},
 	{Name: "decisions:admin", Resource: "decisions", Action: "admin", Description: "Admin access to decisions"},
 
+	// Configuration
+	{Name: "config:read", Resource: "config", Action: "read", Description: "Read system configuration"},
+	{Name: "config:write", Resource: "config", Action: "write", Description: "Change system configuration"},
+
+	// Analytics
+	{Name: "analytics:read", Resource: "analytics", Action: "read", Description: "Read usage analytics, cost reports and request logs"},
+
+	// Users and roles
+	{Name: "users:read", Resource: "users", Action: "read", Description: "Read users and role assignments"},
+	{Name: "users:admin", Resource: "users", Action: "admin", Description: "Create users and assign roles"},
+
 	// System
 	{Name: "repl:use", Resource: "repl", Action: "write", Description: "Use CEO REPL"},
+	{Name: "system:read", Resource: "system", Action: "read", Description: "Read analytics, logs, events and system status"},
+	{Name: "system:write", Resource: "system", Action: "write", Description: "Operate caches, optimizations, motivations and workflows"},
 	{Name: "system:admin", Resource: "system", Action: "admin", Description: "Full system administration"},
 
 	// Catch-all
 	{Name: "*:*", Resource: "*", Action: "*", Description: "All permissions"},
 }
+
+// PreDefinedAPIKeyScopes contains the scopes API keys can be granted
+var PreDefinedAPIKeyScopes = map[string]APIKeyScope{
+	"analytics:read": {
+		Name:        "analytics:read",
+		Description: "Read-only access to analytics, cost reports and request logs",
+		Permissions: []string{"analytics:read"},
+	},
+	"beads:create": {
+		Name:        "beads:create",
+		Description: "Create and update beads, and read projects to file them against",
+		Permissions: []string{"beads:read", "beads:write", "projects:read"},
+	},
+	"admin": {
+		Name:        "admin",
+		Description: "Full administrative access",
+		Permissions: []string{"*:*"},
+	},
+}
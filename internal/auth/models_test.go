@@ -362,7 +362,7 @@ func TestPermission_Struct(t *testing.T) {
 
 // TestPreDefinedRoles tests predefined roles
 func TestPreDefinedRoles(t *testing.T) {
-	expectedRoles := []string{"admin", "user", "viewer", "service"}
+	expectedRoles := []string{"admin", "operator", "user", "viewer", "service"}
 
 	for _, roleName := range expectedRoles {
 		role, ok := PreDefinedRoles[roleName]
@@ -0,0 +1,603 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+const (
+	// oidcLoginTTL is how long a user has to complete a login at the IdP
+	oidcLoginTTL = 10 * time.Minute
+
+	// oidcMaxPendingLogins caps logins in progress, so unfinished ones
+	// cannot grow the pending map without bound
+	oidcMaxPendingLogins = 1000
+
+	// oidcStateCookie binds a login's state to the browser that started it
+	oidcStateCookie = "loom_oidc_state"
+
+	// oidcJWKSRefreshInterval bounds how often signing keys are refetched
+	// when a token names an unknown key
+	oidcJWKSRefreshInterval = time.Minute
+)
+
+// errOIDCBusy is returned when too many logins are in progress
+var errOIDCBusy = errors.New("oidc: too many logins in progress, try again later")
+
+// oidcRolePriority orders roles from most to least privileged, for users
+// whose groups map to several roles
+var oidcRolePriority = []string{"admin", "operator", "user", "viewer", "service"}
+
+// OIDCProvider runs the authorization code flow with PKCE against an
+// OpenID Connect identity provider and verifies the ID tokens it returns
+type OIDCProvider struct {
+	cfg    config.OIDCConfig
+	client *http.Client
+
+	mu          sync.Mutex
+	discovery   *oidcDiscovery
+	keys        map[string]interface{} // kid -> public key
+	keysFetched time.Time
+	pending     map[string]*oidcLogin // state -> login in progress
+}
+
+// oidcDiscovery is the subset of the provider metadata Loom uses
+type oidcDiscovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	TokenAuthMethods      []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+type oidcLogin struct {
+	nonce     string
+	verifier  string
+	createdAt time.Time
+}
+
+// OIDCIdentity is the verified identity from an ID token
+type OIDCIdentity struct {
+	Issuer   string
+	Subject  string
+	Email    string
+	Name     string
+	Username string
+	Groups   []string
+}
+
+// NewOIDCProvider validates cfg and fills in defaults. Discovery happens on
+// first use so the server starts even when the IdP is unreachable.
+func NewOIDCProvider(cfg config.OIDCConfig) (*OIDCProvider, error) {
+	if cfg.IssuerURL == "" || cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("oidc: issuer_url, client_id and redirect_url are required")
+	}
+	if cfg.DefaultRole != "" {
+		if _, ok := PreDefinedRoles[cfg.DefaultRole]; !ok {
+			return nil, fmt.Errorf("oidc: unknown default role: %s", cfg.DefaultRole)
+		}
+	}
+	for group, role := range cfg.GroupRoles {
+		if _, ok := PreDefinedRoles[role]; !ok {
+			return nil, fmt.Errorf("oidc: group %s maps to unknown role: %s", group, role)
+		}
+	}
+	cfg.IssuerURL = strings.TrimSuffix(cfg.IssuerURL, "/")
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"openid", "email", "profile"}
+	} else if !containsString(cfg.Scopes, "openid") {
+		cfg.Scopes = append([]string{"openid"}, cfg.Scopes...)
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.ProviderName == "" {
+		cfg.ProviderName = "SSO"
+	}
+	if cfg.PostLoginRedirect == "" {
+		cfg.PostLoginRedirect = "/"
+	}
+
+	return &OIDCProvider{
+		cfg:     cfg,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		keys:    make(map[string]interface{}),
+		pending: make(map[string]*oidcLogin),
+	}, nil
+}
+
+// Name returns the provider name shown to users
+func (p *OIDCProvider) Name() string {
+	return p.cfg.ProviderName
+}
+
+// AuthCodeURL starts a login and returns the IdP URL to send the browser
+// to, and the login's state, which the caller binds to the browser
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context) (string, string, error) {
+	disc, err := p.discover(ctx)
+	if err != nil {
+		return "", "", err
+	}
+
+	state := generateRandomSecret(16)
+	login := &oidcLogin{
+		nonce:     generateRandomSecret(16),
+		verifier:  generateRandomSecret(32),
+		createdAt: time.Now(),
+	}
+	challenge := sha256.Sum256([]byte(login.verifier))
+
+	p.mu.Lock()
+	for s, l := range p.pending {
+		if time.Since(l.createdAt) > oidcLoginTTL {
+			delete(p.pending, s)
+		}
+	}
+	if len(p.pending) >= oidcMaxPendingLogins {
+		p.mu.Unlock()
+		return "", "", errOIDCBusy
+	}
+	p.pending[state] = login
+	p.mu.Unlock()
+
+	q := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.cfg.ClientID},
+		"redirect_uri":          {p.cfg.RedirectURL},
+		"scope":                 {strings.Join(p.cfg.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {login.nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	sep := "?"
+	if strings.Contains(disc.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+	return disc.AuthorizationEndpoint + sep + q.Encode(), state, nil
+}
+
+// Exchange completes a login: it redeems the authorization code and
+// verifies the returned ID token against the login's state and nonce
+func (p *OIDCProvider) Exchange(ctx context.Context, state, code string) (*OIDCIdentity, error) {
+	p.mu.Lock()
+	login, ok := p.pending[state]
+	delete(p.pending, state)
+	p.mu.Unlock()
+	if !ok || time.Since(login.createdAt) > oidcLoginTTL {
+		return nil, fmt.Errorf("oidc: unknown or expired login state")
+	}
+
+	disc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {p.cfg.RedirectURL},
+		"client_id":     {p.cfg.ClientID},
+		"code_verifier": {login.verifier},
+	}
+	useBasic := p.cfg.ClientSecret != "" && !containsString(disc.TokenAuthMethods, "client_secret_post") &&
+		len(disc.TokenAuthMethods) > 0
+	if p.cfg.ClientSecret != "" && !useBasic {
+		form.Set("client_secret", p.cfg.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, disc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.ClientID), url.QueryEscape(p.cfg.ClientSecret))
+	}
+
+	var tokens struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.doJSON(req, &tokens); err != nil {
+		return nil, fmt.Errorf("oidc: token exchange failed: %w", err)
+	}
+	if tokens.Error != "" {
+		return nil, fmt.Errorf("oidc: token exchange failed: %s %s", tokens.Error, tokens.ErrorDescription)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("oidc: token response has no id_token")
+	}
+
+	return p.verifyIDToken(ctx, disc, tokens.IDToken, login.nonce)
+}
+
+// verifyIDToken checks an ID token's signature, issuer, audience, expiry
+// and nonce, and extracts the identity from it
+func (p *OIDCProvider) verifyIDToken(ctx context.Context, disc *oidcDiscovery, raw, nonce string) (*OIDCIdentity, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return p.signingKey(ctx, disc, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(disc.Issuer),
+		jwt.WithAudience(p.cfg.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("oidc: invalid id_token: %w", err)
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("oidc: id_token nonce mismatch")
+	}
+
+	id := &OIDCIdentity{Issuer: disc.Issuer}
+	id.Subject, _ = claims["sub"].(string)
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	id.Username, _ = claims["preferred_username"].(string)
+	if id.Subject == "" {
+		return nil, fmt.Errorf("oidc: id_token has no subject")
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified {
+		id.Email = ""
+	}
+	switch groups := claims[p.cfg.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				id.Groups = append(id.Groups, s)
+			}
+		}
+	case string:
+		id.Groups = []string{groups}
+	}
+	return id, nil
+}
+
+// resolveRole maps an identity to a Loom role: the most privileged role of
+// its groups, else the default role. It fails when the identity is not
+// allowed in at all.
+func (p *OIDCProvider) resolveRole(id *OIDCIdentity) (string, error) {
+	if len(p.cfg.AllowedDomains) > 0 {
+		at := strings.LastIndex(id.Email, "@")
+		if at < 0 || !containsFold(p.cfg.AllowedDomains, id.Email[at+1:]) {
+			return "", fmt.Errorf("email domain not allowed")
+		}
+	}
+
+	mapped := make(map[string]bool)
+	for _, g := range id.Groups {
+		if role, ok := p.cfg.GroupRoles[g]; ok {
+			mapped[role] = true
+		}
+	}
+	for _, role := range oidcRolePriority {
+		if mapped[role] {
+			return role, nil
+		}
+	}
+	if p.cfg.DefaultRole != "" {
+		return p.cfg.DefaultRole, nil
+	}
+	return "", fmt.Errorf("no role mapped for the user's groups")
+}
+
+// discover fetches and caches the provider metadata
+func (p *OIDCProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	disc := p.discovery
+	p.mu.Unlock()
+	if disc != nil {
+		return disc, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.cfg.IssuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	disc = &oidcDiscovery{}
+	if err := p.doJSON(req, disc); err != nil {
+		return nil, fmt.Errorf("oidc: discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(disc.Issuer, "/") != p.cfg.IssuerURL {
+		return nil, fmt.Errorf("oidc: discovery issuer %q does not match %q", disc.Issuer, p.cfg.IssuerURL)
+	}
+	if disc.AuthorizationEndpoint == "" || disc.TokenEndpoint == "" || disc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc: discovery document is missing endpoints")
+	}
+
+	p.mu.Lock()
+	p.discovery = disc
+	p.mu.Unlock()
+	return disc, nil
+}
+
+// signingKey returns the provider's public key with the given ID,
+// refetching the key set when the key is unknown (the IdP rotated keys)
+func (p *OIDCProvider) signingKey(ctx context.Context, disc *oidcDiscovery, kid string) (interface{}, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	stale := time.Since(p.keysFetched) >= oidcJWKSRefreshInterval
+	p.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, disc.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.doJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	keys := make(map[string]interface{})
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if pub, err := jwk.publicKey(); err == nil {
+			keys[jwk.Kid] = pub
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.keysFetched = time.Now()
+	p.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *OIDCProvider) doJSON(req *http.Request, out interface{}) error {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	// Token endpoints report errors as JSON with a 400 status
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%s returned %d", req.URL.Host, resp.StatusCode)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// jsonWebKey is an RSA or EC public key from a JWKS document
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", k.Kty)
+}
+
+// EnableOIDC turns on single sign-on through an OpenID Connect provider
+func (m *Manager) EnableOIDC(cfg config.OIDCConfig) error {
+	provider, err := NewOIDCProvider(cfg)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.oidc = provider
+	m.mu.Unlock()
+	return nil
+}
+
+// OIDC returns the SSO provider, or nil when SSO is not enabled
+func (m *Manager) OIDC() *OIDCProvider {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.oidc
+}
+
+// LoginOIDC completes an SSO login and issues a Loom token. Users are
+// provisioned on first login and their role is re-synced from their groups
+// on every login, so removing someone from a group takes effect at once.
+func (m *Manager) LoginOIDC(ctx context.Context, state, code string) (*LoginResponse, error) {
+	provider := m.OIDC()
+	if provider == nil {
+		return nil, fmt.Errorf("SSO is not enabled")
+	}
+	id, err := provider.Exchange(ctx, state, code)
+	if err != nil {
+		return nil, err
+	}
+
+	role, err := provider.resolveRole(id)
+	if err != nil {
+		observability.SecurityAudit("auth.oidc_login_denied", map[string]interface{}{
+			"issuer":  id.Issuer,
+			"subject": id.Subject,
+			"email":   id.Email,
+			"reason":  err.Error(),
+		})
+		return nil, fmt.Errorf("access denied: %w", err)
+	}
+
+	user, err := m.provisionOIDCUser(id, role)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := m.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+	observability.SecurityAudit("auth.oidc_login", map[string]interface{}{
+		"user_id": user.ID,
+		"issuer":  id.Issuer,
+		"subject": id.Subject,
+		"role":    role,
+	})
+	return &LoginResponse{
+		Token:     token,
+		ExpiresIn: int64(m.tokenTTL.Seconds()),
+		User:      *user,
+	}, nil
+}
+
+// provisionOIDCUser finds the user linked to an identity, creating it on
+// first login. Identities are never linked to local users by name.
+func (m *Manager) provisionOIDCUser(id *OIDCIdentity, role string) (*User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, u := range m.users {
+		if u.AuthProvider != id.Issuer || u.ExternalID != id.Subject {
+			continue
+		}
+		if !u.IsActive {
+			return nil, fmt.Errorf("user is deactivated")
+		}
+		if u.Role != role {
+			observability.SecurityAudit("auth.oidc_role_synced", map[string]interface{}{
+				"user_id":  u.ID,
+				"old_role": u.Role,
+				"new_role": role,
+			})
+			u.Role = role
+		}
+		if id.Email != "" {
+			u.Email = id.Email
+		}
+		u.UpdatedAt = time.Now()
+		return u, nil
+	}
+
+	base := id.Username
+	if base == "" {
+		base = id.Email
+	}
+	if base == "" {
+		base = id.Subject
+	}
+	username := base
+	for n := 2; m.usernameTaken(username); n++ {
+		username = fmt.Sprintf("%s-%d", base, n)
+	}
+
+	now := time.Now()
+	user := &User{
+		ID:           generateRandomID(),
+		Username:     username,
+		Email:        id.Email,
+		Role:         role,
+		AuthProvider: id.Issuer,
+		ExternalID:   id.Subject,
+		IsActive:     true,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	m.users[user.ID] = user
+
+	observability.SecurityAudit("auth.oidc_user_provisioned", map[string]interface{}{
+		"user_id":  user.ID,
+		"username": username,
+		"issuer":   id.Issuer,
+		"subject":  id.Subject,
+		"role":     role,
+	})
+	return user, nil
+}
+
+// usernameTaken reports whether a username is in use. Callers hold m.mu.
+func (m *Manager) usernameTaken(username string) bool {
+	for _, u := range m.users {
+		if u.Username == username {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,346 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// fakeIdP is a minimal OpenID Connect provider. Each authorization code
+// maps to the claims of the ID token it is redeemed for.
+type fakeIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	claims map[string]jwt.MapClaims // code -> ID token claims
+	logins map[string]url.Values    // state -> authorization request
+}
+
+func newFakeIdP(t *testing.T) *fakeIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	idp := &fakeIdP{t: t, key: key, claims: make(map[string]jwt.MapClaims), logins: make(map[string]url.Values)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize",
+			"token_endpoint":         idp.server.URL + "/token",
+			"jwks_uri":               idp.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		claims, ok := idp.claims[r.Form.Get("code")]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		// PKCE: the verifier must hash to the challenge sent at login
+		login := idp.logins[claims["state"].(string)]
+		sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != login.Get("code_challenge") {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant", "error_description": "PKCE"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"id_token": idp.sign(claims)})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *fakeIdP) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "k1"
+	signed, err := token.SignedString(idp.key)
+	if err != nil {
+		idp.t.Fatalf("SignedString failed: %v", err)
+	}
+	return signed
+}
+
+// login runs the browser side of the flow and returns the callback's
+// state and code for a user with the given subject and groups
+func (idp *fakeIdP) login(p *OIDCProvider, sub, email string, groups []string, override jwt.MapClaims) (string, string) {
+	authURL, _, err := p.AuthCodeURL(context.Background())
+	if err != nil {
+		idp.t.Fatalf("AuthCodeURL failed: %v", err)
+	}
+	return idp.authorize(authURL, sub, email, groups, override)
+}
+
+// authorize plays the IdP's part for an authorization request and returns
+// the callback's state and code
+func (idp *fakeIdP) authorize(authURL, sub, email string, groups []string, override jwt.MapClaims) (string, string) {
+	u, _ := url.Parse(authURL)
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" || q.Get("client_id") != "loom" {
+		idp.t.Fatalf("unexpected authorization request: %s", authURL)
+	}
+	state := q.Get("state")
+	idp.logins[state] = q
+
+	claims := jwt.MapClaims{
+		"iss":                idp.server.URL,
+		"aud":                "loom",
+		"sub":                sub,
+		"email":              email,
+		"preferred_username": strings.Split(email, "@")[0],
+		"groups":             groups,
+		"nonce":              q.Get("nonce"),
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"iat":                time.Now().Unix(),
+		"state":              state,
+	}
+	for k, v := range override {
+		claims[k] = v
+	}
+	code := "code-" + state
+	idp.claims[code] = claims
+	return state, code
+}
+
+func newOIDCManager(t *testing.T, idp *fakeIdP, mutate func(*config.OIDCConfig)) *Manager {
+	cfg := config.OIDCConfig{
+		Enabled:        true,
+		IssuerURL:      idp.server.URL,
+		ClientID:       "loom",
+		ClientSecret:   "shh",
+		RedirectURL:    "https://loom.example.com/api/v1/auth/oidc/callback",
+		GroupRoles:     map[string]string{"loom-admins": "admin", "engineering": "user"},
+		AllowedDomains: []string{"example.com"},
+	}
+	if mutate != nil {
+		mutate(&cfg)
+	}
+	m := NewManager("test-secret")
+	if err := m.EnableOIDC(cfg); err != nil {
+		t.Fatalf("EnableOIDC failed: %v", err)
+	}
+	return m
+}
+
+func TestLoginOIDC_ProvisionsAndSyncsRole(t *testing.T) {
+	idp := newFakeIdP(t)
+	m := newOIDCManager(t, idp, nil)
+
+	state, code := idp.login(m.OIDC(), "okta|123", "dana@example.com", []string{"engineering", "loom-admins"}, nil)
+	resp, err := m.LoginOIDC(context.Background(), state, code)
+	if err != nil {
+		t.Fatalf("LoginOIDC failed: %v", err)
+	}
+	if resp.User.Role != "admin" || resp.User.Username != "dana" || resp.User.ExternalID != "okta|123" {
+		t.Errorf("unexpected provisioned user: %+v", resp.User)
+	}
+	claims, err := m.ValidateToken(resp.Token)
+	if err != nil || claims.UserID != resp.User.ID {
+		t.Fatalf("issued token does not validate: %v", err)
+	}
+
+	// SSO users have no local password
+	if _, err := m.Login("dana", ""); err == nil {
+		t.Error("SSO user must not log in with a password")
+	}
+
+	// The role follows group membership on the next login
+	state, code = idp.login(m.OIDC(), "okta|123", "dana@example.com", []string{"engineering"}, nil)
+	again, err := m.LoginOIDC(context.Background(), state, code)
+	if err != nil {
+		t.Fatalf("second LoginOIDC failed: %v", err)
+	}
+	if again.User.ID != resp.User.ID || again.User.Role != "user" {
+		t.Errorf("expected same user demoted to user, got %+v", again.User)
+	}
+
+	// A code can only be redeemed with its own state
+	if _, err := m.LoginOIDC(context.Background(), state, code); err == nil {
+		t.Error("expected replayed state to be rejected")
+	}
+}
+
+func TestLoginOIDC_DeniesUnmappedAndForeignUsers(t *testing.T) {
+	idp := newFakeIdP(t)
+	m := newOIDCManager(t, idp, nil)
+
+	state, code := idp.login(m.OIDC(), "okta|1", "eve@example.com", []string{"sales"}, nil)
+	if _, err := m.LoginOIDC(context.Background(), state, code); err == nil {
+		t.Error("expected user without a mapped group to be denied")
+	}
+
+	state, code = idp.login(m.OIDC(), "okta|2", "mallory@evil.com", []string{"loom-admins"}, nil)
+	if _, err := m.LoginOIDC(context.Background(), state, code); err == nil {
+		t.Error("expected user outside the allowed domains to be denied")
+	}
+
+	withDefault := newOIDCManager(t, idp, func(c *config.OIDCConfig) { c.DefaultRole = "viewer" })
+	state, code = idp.login(withDefault.OIDC(), "okta|1", "eve@example.com", []string{"sales"}, nil)
+	resp, err := withDefault.LoginOIDC(context.Background(), state, code)
+	if err != nil || resp.User.Role != "viewer" {
+		t.Errorf("expected default role viewer, got %+v (%v)", resp, err)
+	}
+}
+
+func TestLoginOIDC_RejectsInvalidIDTokens(t *testing.T) {
+	idp := newFakeIdP(t)
+	m := newOIDCManager(t, idp, nil)
+
+	for name, override := range map[string]jwt.MapClaims{
+		"wrong nonce":    {"nonce": "forged"},
+		"wrong audience": {"aud": "other-client"},
+		"wrong issuer":   {"iss": "https://evil.example.com"},
+		"expired":        {"exp": time.Now().Add(-time.Hour).Unix()},
+	} {
+		state, code := idp.login(m.OIDC(), "okta|123", "dana@example.com", []string{"engineering"}, override)
+		if _, err := m.LoginOIDC(context.Background(), state, code); err == nil {
+			t.Errorf("%s: expected ID token to be rejected", name)
+		}
+	}
+}
+
+func TestLoginOIDC_NeverLinksLocalUsers(t *testing.T) {
+	idp := newFakeIdP(t)
+	m := newOIDCManager(t, idp, nil)
+
+	// A local "admin" exists; an SSO user named admin gets a distinct account
+	state, code := idp.login(m.OIDC(), "okta|9", "admin@example.com", []string{"engineering"}, nil)
+	resp, err := m.LoginOIDC(context.Background(), state, code)
+	if err != nil {
+		t.Fatalf("LoginOIDC failed: %v", err)
+	}
+	if resp.User.ID == "user-admin" || resp.User.Username != "admin-2" || resp.User.Role != "user" {
+		t.Errorf("SSO user must not take over the local admin: %+v", resp.User)
+	}
+}
+
+func TestOIDCHandlers_RedirectFlow(t *testing.T) {
+	idp := newFakeIdP(t)
+	m := newOIDCManager(t, idp, nil)
+	h := NewHandlers(m)
+
+	rec := httptest.NewRecorder()
+	h.HandleOIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), idp.server.URL+"/authorize?") {
+		t.Fatalf("expected redirect to the IdP, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	var stateCookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcStateCookie {
+			stateCookie = c
+		}
+	}
+	if stateCookie == nil || !stateCookie.HttpOnly || stateCookie.SameSite != http.SameSiteLaxMode ||
+		stateCookie.Path != "/api/v1/auth/oidc/callback" || stateCookie.MaxAge <= 0 {
+		t.Fatalf("expected a short-lived HttpOnly state cookie, got %+v", stateCookie)
+	}
+
+	state, code := idp.authorize(rec.Header().Get("Location"), "okta|123", "dana@example.com", []string{"engineering"}, nil)
+	if stateCookie.Value != state {
+		t.Fatalf("state cookie %q does not match state %q", stateCookie.Value, state)
+	}
+	callback := "/api/v1/auth/oidc/callback?state=" + state + "&code=" + code
+
+	// A callback from a browser that did not start the login is refused,
+	// and does not use up the login
+	rec = httptest.NewRecorder()
+	h.HandleOIDCCallback(rec, httptest.NewRequest(http.MethodGet, callback, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without the state cookie, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(&http.Cookie{Name: oidcStateCookie, Value: "someone-elses-state"})
+	h.HandleOIDCCallback(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a mismatched state cookie, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(stateCookie)
+	h.HandleOIDCCallback(rec, req)
+	if rec.Code != http.StatusFound || !strings.HasPrefix(rec.Header().Get("Location"), "/#sso_token=") {
+		t.Fatalf("expected redirect to the UI with a token, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == oidcStateCookie && c.MaxAge >= 0 {
+			t.Errorf("expected the state cookie to be cleared, got %+v", c)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	h.HandleOIDCCallback(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/callback?error=access_denied", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for IdP error, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	NewHandlers(NewManager("x")).HandleOIDCLogin(rec, httptest.NewRequest(http.MethodGet, "/api/v1/auth/oidc/login", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 when SSO is disabled, got %d", rec.Code)
+	}
+}
+
+func TestOIDCProvider_CapsPendingLogins(t *testing.T) {
+	idp := newFakeIdP(t)
+	p := newOIDCManager(t, idp, nil).OIDC()
+	for i := 0; i < oidcMaxPendingLogins; i++ {
+		if _, _, err := p.AuthCodeURL(context.Background()); err != nil {
+			t.Fatalf("AuthCodeURL %d failed: %v", i, err)
+		}
+	}
+	if _, _, err := p.AuthCodeURL(context.Background()); err != errOIDCBusy {
+		t.Fatalf("expected errOIDCBusy once the cap is reached, got %v", err)
+	}
+
+	// Expired logins make room again
+	p.mu.Lock()
+	for _, l := range p.pending {
+		l.createdAt = time.Now().Add(-2 * oidcLoginTTL)
+	}
+	p.mu.Unlock()
+	if _, _, err := p.AuthCodeURL(context.Background()); err != nil {
+		t.Fatalf("AuthCodeURL after expiry failed: %v", err)
+	}
+}
+
+func TestNewOIDCProvider_ValidatesConfig(t *testing.T) {
+	if _, err := NewOIDCProvider(config.OIDCConfig{IssuerURL: "https://idp"}); err == nil {
+		t.Error("expected error for missing client_id")
+	}
+	_, err := NewOIDCProvider(config.OIDCConfig{
+		IssuerURL: "https://idp", ClientID: "c", RedirectURL: "https://loom/cb",
+		GroupRoles: map[string]string{"g": "superuser"},
+	})
+	if err == nil {
+		t.Error("expected error for unknown mapped role")
+	}
+}
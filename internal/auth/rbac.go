@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// Principal is an authenticated caller
+type Principal struct {
+	UserID   string
+	Username string
+	Role     string
+	// OrgID is the caller's organization; "" when it is not known
+	OrgID string
+	// APIKeyPermissions is set for API key callers, whose access is limited
+	// to the key's grants rather than the owner's roles
+	APIKeyPermissions []string
+	IsAPIKey          bool
+}
+
+// AssignRole sets a user's global role, or grants a project-scoped role
+// when projectID is set. A project binding replaces any previous binding
+// for the same project.
+func (m *Manager) AssignRole(userID, role, projectID, grantedBy string) (*RoleBinding, error) {
+	if _, exists := m.roles[role]; !exists {
+		return nil, fmt.Errorf("unknown role: %s", role)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	user, exists := m.users[userID]
+	if !exists {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	binding := RoleBinding{
+		UserID:    userID,
+		Role:      role,
+		ProjectID: projectID,
+		GrantedBy: grantedBy,
+		GrantedAt: time.Now(),
+	}
+	if projectID == "" {
+		user.Role = role
+		user.UpdatedAt = binding.GrantedAt
+	} else {
+		bindings := m.bindings[userID][:0:0]
+		for _, b := range m.bindings[userID] {
+			if b.ProjectID != projectID {
+				bindings = append(bindings, b)
+			}
+		}
+		m.bindings[userID] = append(bindings, binding)
+	}
+
+	observability.SecurityAudit("auth.role_assigned", map[string]interface{}{
+		"actor":      grantedBy,
+		"user_id":    userID,
+		"role":       role,
+		"project_id": projectID,
+	})
+	return &binding, nil
+}
+
+// RevokeProjectRole removes a user's role binding for a project
+func (m *Manager) RevokeProjectRole(userID, projectID, revokedBy string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bindings := m.bindings[userID]
+	for i, b := range bindings {
+		if b.ProjectID == projectID {
+			m.bindings[userID] = append(bindings[:i:i], bindings[i+1:]...)
+			observability.SecurityAudit("auth.role_revoked", map[string]interface{}{
+				"actor":      revokedBy,
+				"user_id":    userID,
+				"role":       b.Role,
+				"project_id": projectID,
+			})
+			return nil
+		}
+	}
+	return fmt.Errorf("no role binding for project %s", projectID)
+}
+
+// ListRoleBindings returns a user's project-scoped role bindings
+func (m *Manager) ListRoleBindings(userID string) []RoleBinding {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	out := make([]RoleBinding, len(m.bindings[userID]))
+	copy(out, m.bindings[userID])
+	return out
+}
+
+// ListRoles returns all roles sorted by name
+func (m *Manager) ListRoles() []Role {
+	roles := make([]Role, 0, len(m.roles))
+	for _, r := range m.roles {
+		roles = append(roles, r)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles
+}
+
+// Authorize reports whether a principal holds a permission, either through
+// its global role or through a role bound to projectID. The user's current
+// role is used rather than the one in their token, so role changes take
+// effect immediately. An API key holds only the permissions that both its
+// own grants and its owner's current roles allow, so demoting the owner
+// narrows the key too; keys stop working when their owner is deactivated.
+// Callers confined to an organization are refused projects of other
+// organizations whatever their roles.
+func (m *Manager) Authorize(p *Principal, permission, projectID string) bool {
+	if p == nil {
+		return false
+	}
+	if permission == "" {
+		return true
+	}
+
+	role, active := m.currentRole(p)
+	if !active {
+		return false
+	}
+	if projectID != "" && !m.projectInOrg(projectID, m.currentOrg(p)) {
+		return false
+	}
+	if p.IsAPIKey && !matchPermission(p.APIKeyPermissions, permission) {
+		return false
+	}
+
+	m.mu.RLock()
+	var projectRole string
+	if projectID != "" {
+		for _, b := range m.bindings[p.UserID] {
+			if b.ProjectID == projectID {
+				projectRole = b.Role
+				break
+			}
+		}
+	}
+	m.mu.RUnlock()
+
+	if r, ok := m.roles[role]; ok && matchPermission(r.Permissions, permission) {
+		return true
+	}
+	if r, ok := m.roles[projectRole]; ok && matchPermission(r.Permissions, permission) {
+		return true
+	}
+	return false
+}
+
+// UserAuthorized reports whether a known, active user holds permission
+// through their own roles. Unlike Authorize, users the manager does not
+// know are refused.
+func (m *Manager) UserAuthorized(userID, permission, projectID string) bool {
+	m.mu.RLock()
+	user, ok := m.users[userID]
+	m.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	return m.Authorize(&Principal{UserID: user.ID, Username: user.Username, Role: user.Role, OrgID: user.OrgID}, permission, projectID)
+}
+
+// currentRole returns the principal's global role as the manager knows it
+// now, and whether the account is active. Principals the manager does not
+// know keep the role they authenticated with.
+func (m *Manager) currentRole(p *Principal) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if user, ok := m.users[p.UserID]; ok {
+		return user.Role, user.IsActive
+	}
+	return p.Role, true
+}
+
+// currentOrg returns the principal's organization as the manager knows it
+// now. Principals the manager does not know keep the one they
+// authenticated with.
+func (m *Manager) currentOrg(p *Principal) string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if user, ok := m.users[p.UserID]; ok {
+		return org.Normalize(user.OrgID)
+	}
+	return p.OrgID
+}
+
+// projectInOrg reports whether a caller of organization orgID may reach a
+// project. Unconfined callers reach every project; projects whose
+// organization is unknown are left to the handlers, which will not find
+// them.
+func (m *Manager) projectInOrg(projectID, orgID string) bool {
+	if !org.Confined(orgID) {
+		return true
+	}
+	m.mu.RLock()
+	resolve := m.projectOrg
+	m.mu.RUnlock()
+	if resolve == nil {
+		return true
+	}
+	owner := resolve(projectID)
+	return owner == "" || org.Normalize(owner) == orgID
+}
+
+// matchPermission checks a permission against grants, honouring "*:*" and
+// resource wildcards such as "agents:*"
+func matchPermission(granted []string, permission string) bool {
+	resource := permission
+	if i := strings.Index(permission, ":"); i >= 0 {
+		resource = permission[:i]
+	}
+	for _, p := range granted {
+		if p == permission || p == "*:*" || p == resource+":*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+func TestAuthorize_GlobalAndProjectRoles(t *testing.T) {
+	m := NewManager("test-secret")
+	user, err := m.CreateUser("alice", "alice@test.com", "viewer", "pw")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	p := &Principal{UserID: user.ID, Username: "alice", Role: "viewer"}
+
+	if !m.Authorize(p, "beads:read", "") {
+		t.Error("viewer should read beads")
+	}
+	if m.Authorize(p, "beads:write", "proj-1") {
+		t.Error("viewer should not write beads")
+	}
+
+	if _, err := m.AssignRole(user.ID, "operator", "proj-1", "user-admin"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if !m.Authorize(p, "beads:write", "proj-1") {
+		t.Error("project operator should write beads in proj-1")
+	}
+	if m.Authorize(p, "beads:write", "proj-2") {
+		t.Error("project binding must not apply to other projects")
+	}
+	if m.Authorize(p, "beads:write", "") {
+		t.Error("project binding must not apply to unscoped requests")
+	}
+
+	if err := m.RevokeProjectRole(user.ID, "proj-1", "user-admin"); err != nil {
+		t.Fatalf("RevokeProjectRole failed: %v", err)
+	}
+	if m.Authorize(p, "beads:write", "proj-1") {
+		t.Error("revoked binding should no longer grant access")
+	}
+
+	// Global role changes apply to existing tokens immediately
+	if _, err := m.AssignRole(user.ID, "admin", "", "user-admin"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if !m.Authorize(p, "system:admin", "") {
+		t.Error("promoted user should be authorized as admin")
+	}
+
+	if _, err := m.AssignRole(user.ID, "superuser", "", "user-admin"); err == nil {
+		t.Error("expected error for unknown role")
+	}
+	if _, err := m.AssignRole("missing", "viewer", "", "user-admin"); err == nil {
+		t.Error("expected error for unknown user")
+	}
+}
+
+func TestAuthorize_APIKeyUsesKeyPermissions(t *testing.T) {
+	m := NewManager("test-secret")
+	p := &Principal{UserID: "user-admin", IsAPIKey: true, APIKeyPermissions: []string{"beads:*"}}
+
+	if !m.Authorize(p, "beads:write", "") {
+		t.Error("key with beads:* should write beads")
+	}
+	if m.Authorize(p, "config:write", "") {
+		t.Error("key should not inherit its admin owner's permissions")
+	}
+}
+
+func TestAuthorize_APIKeyLimitedByOwnerRole(t *testing.T) {
+	m := NewManager("test-secret")
+	user, err := m.CreateUser("carol", "carol@test.com", "operator", "pw")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	p := &Principal{UserID: user.ID, IsAPIKey: true, APIKeyPermissions: []string{"beads:*", "system:admin"}}
+
+	if !m.Authorize(p, "beads:write", "") {
+		t.Error("key should write beads while its owner may")
+	}
+	if m.Authorize(p, "system:admin", "") {
+		t.Error("key must not grant more than its owner holds")
+	}
+
+	// Demoting the owner narrows the key at once
+	if _, err := m.AssignRole(user.ID, "viewer", "", "user-admin"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if m.Authorize(p, "beads:write", "") {
+		t.Error("key should lose beads:write when its owner is demoted")
+	}
+	if !m.Authorize(p, "beads:read", "") {
+		t.Error("key should keep what the demoted owner still holds")
+	}
+
+	// A project binding of the owner reaches the key for that project only
+	if _, err := m.AssignRole(user.ID, "operator", "proj-1", "user-admin"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	if !m.Authorize(p, "beads:write", "proj-1") || m.Authorize(p, "beads:write", "proj-2") {
+		t.Error("key should follow its owner's project bindings")
+	}
+
+	orphan := &Principal{UserID: "user-gone", IsAPIKey: true, APIKeyPermissions: []string{"beads:read"}}
+	if m.Authorize(orphan, "beads:read", "") {
+		t.Error("keys of unknown owners should be refused")
+	}
+}
+
+func TestUserAuthorized(t *testing.T) {
+	m := NewManager("test-secret")
+	viewer, err := m.CreateUser("alice", "alice@test.com", "viewer", "pw")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	admin, err := m.CreateUser("bob", "bob@test.com", "admin", "pw")
+	if err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	if m.UserAuthorized(viewer.ID, "decisions:admin", "proj-1") {
+		t.Error("viewer should not administer decisions")
+	}
+	if !m.UserAuthorized(admin.ID, "decisions:admin", "proj-1") {
+		t.Error("admin should administer decisions")
+	}
+	if m.UserAuthorized("unknown", "beads:read", "") {
+		t.Error("unknown users should be refused")
+	}
+}
+
+func TestRequirePermission_IgnoresSpoofedIdentity(t *testing.T) {
+	m := NewManager("test-secret")
+	viewer, _ := m.CreateUser("bob", "bob@test.com", "viewer", "pw")
+	token, err := m.GenerateToken(viewer)
+	if err != nil {
+		t.Fatalf("GenerateToken failed: %v", err)
+	}
+
+	var gotRole string
+	handler := m.RequirePermission(func(*http.Request) (string, string) {
+		return "config:write", ""
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotRole = GetRoleFromRequest(r)
+	}))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/config", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Role", "admin")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected 403 for viewer, got %d", rec.Code)
+	}
+
+	if _, err := m.AssignRole(viewer.ID, "admin", "", "user-admin"); err != nil {
+		t.Fatalf("AssignRole failed: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || gotRole != "admin" {
+		t.Errorf("expected promoted user to pass with role admin, got %d / %q", rec.Code, gotRole)
+	}
+}
+
+func TestAuthorize_ConfinesOrganizationMembers(t *testing.T) {
+	orgs, err := org.NewManager(nil)
+	if err != nil {
+		t.Fatalf("org.NewManager failed: %v", err)
+	}
+	if _, err := orgs.Create(org.Request{ID: "acme"}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	m := NewManager("test-secret")
+	m.SetOrgs(orgs)
+	m.SetProjectOrgResolver(func(projectID string) string {
+		return map[string]string{"proj-acme": "acme", "proj-default": org.DefaultID}[projectID]
+	})
+
+	if _, err := m.CreateUserInOrg("globex", "gus", "gus@test.com", "admin", "pw"); err == nil {
+		t.Error("expected a user in an unknown organization to be rejected")
+	}
+	acmeAdmin, err := m.CreateUserInOrg("acme", "ann", "ann@test.com", "admin", "pw")
+	if err != nil {
+		t.Fatalf("CreateUserInOrg failed: %v", err)
+	}
+	p := &Principal{UserID: acmeAdmin.ID, Role: "admin"}
+	if !m.Authorize(p, "projects:write", "proj-acme") {
+		t.Error("acme admin should write acme's project")
+	}
+	if m.Authorize(p, "projects:read", "proj-default") {
+		t.Error("acme admin must not reach the default organization's project")
+	}
+	// Stale org claims do not matter: the user's current organization does
+	if m.Authorize(&Principal{UserID: acmeAdmin.ID, Role: "admin", OrgID: org.DefaultID}, "projects:read", "proj-default") {
+		t.Error("acme admin must not escape through a forged organization")
+	}
+
+	operator := &Principal{UserID: "user-admin", Role: "admin"}
+	if !m.Authorize(operator, "projects:read", "proj-acme") {
+		t.Error("default organization admin should reach every organization's projects")
+	}
+
+	if users := m.ListUsersInOrg("acme"); len(users) != 1 || users[0].ID != acmeAdmin.ID {
+		t.Errorf("ListUsersInOrg(acme) = %v", users)
+	}
+}
+
+func TestRequirePermission_PublishesOrganizationOfConfinedCallers(t *testing.T) {
+	orgs, _ := org.NewManager(nil)
+	_, _ = orgs.Create(org.Request{ID: "acme"})
+	m := NewManager("test-secret")
+	m.SetOrgs(orgs)
+	acme, _ := m.CreateUserInOrg("acme", "ann", "ann@test.com", "user", "pw")
+	operator, _ := m.CreateUser("olga", "olga@test.com", "user", "pw")
+
+	var gotOrg string
+	handler := m.RequirePermission(func(*http.Request) (string, string) {
+		return "beads:read", ""
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotOrg = GetOrgIDFromRequest(r)
+	}))
+
+	for _, tt := range []struct {
+		user    *User
+		wantOrg string
+	}{{acme, "acme"}, {operator, ""}} {
+		token, err := m.GenerateToken(tt.user)
+		if err != nil {
+			t.Fatalf("GenerateToken failed: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/beads", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("X-Org-ID", "spoofed")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if gotOrg != tt.wantOrg {
+			t.Errorf("%s: X-Org-ID = %q, want %q", tt.user.Username, gotOrg, tt.wantOrg)
+		}
+	}
+}
@@ -0,0 +1,610 @@
+// Package backup takes copies of the Loom database and key store, on a
+// schedule and on demand, and restores them. Each backup is a directory
+// holding the database dump, the key store file and a manifest recording
+// the schema version and a checksum of every file, so a backup can be
+// verified before it is trusted. Old backups are pruned by count and age.
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Backup triggers
+const (
+	TriggerManual     = "manual"
+	TriggerSchedule   = "schedule"
+	TriggerPreRestore = "pre_restore" // Taken by Restore before it overwrites anything
+)
+
+// File kinds
+const (
+	KindDatabase = "database"
+	KindKeystore = "keystore"
+)
+
+// manifestName is the file in each backup directory that describes it
+const manifestName = "manifest.json"
+
+// Backup describes one backup
+type Backup struct {
+	ID            string        `json:"id"`
+	Trigger       string        `json:"trigger"`
+	DatabaseType  string        `json:"database_type"`
+	SchemaVersion int64         `json:"schema_version"`
+	Files         []File        `json:"files"`
+	SizeBytes     int64         `json:"size_bytes"`
+	CreatedBy     string        `json:"created_by,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	Verification  *Verification `json:"verification,omitempty"` // The latest check
+}
+
+// File is one file of a backup
+type File struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	SizeBytes int64  `json:"size_bytes"`
+	SHA256    string `json:"sha256"`
+}
+
+// Verification is the outcome of checking a backup
+type Verification struct {
+	OK         bool      `json:"ok"`
+	Problems   []string  `json:"problems,omitempty"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// Database is the database being backed up
+type Database interface {
+	// Type is "sqlite" or "postgres"
+	Type() string
+	// SchemaVersion is the newest migration applied to the database
+	SchemaVersion(ctx context.Context) (int64, error)
+	// LatestSchemaVersion is the newest migration this binary knows
+	LatestSchemaVersion() (int64, error)
+	// Dump writes a consistent copy of the live database to path
+	Dump(ctx context.Context, path string) error
+	// VerifyDump checks a dump and returns its schema version, or -1 if
+	// the dump cannot tell
+	VerifyDump(ctx context.Context, path string) (int64, error)
+	// Restore replaces the database with a dump
+	Restore(ctx context.Context, path string) error
+	// Migrate applies pending schema migrations
+	Migrate(ctx context.Context) error
+}
+
+// Keystore is the encrypted key store saved alongside the database
+type Keystore interface {
+	// Export returns the key store file, or nil if there is none
+	Export() ([]byte, error)
+	// Import replaces the key store file
+	Import(data []byte) error
+}
+
+// Config tunes where backups go, how often they are taken and how long
+// they are kept
+type Config struct {
+	Dir          string        // Directory holding one subdirectory per backup
+	Interval     time.Duration // Time between scheduled backups; 0 disables them
+	Keep         int           // Newest backups kept; 0 keeps every one
+	MaxAge       time.Duration // Backups older than this are removed; 0 keeps them
+	PollInterval time.Duration // How often a scheduled backup is looked for
+}
+
+// DefaultConfig keeps two weeks of daily backups under ./backups, but takes
+// none on a schedule until Interval is set
+func DefaultConfig() Config {
+	return Config{
+		Dir:          "backups",
+		Keep:         14,
+		PollInterval: time.Minute,
+	}
+}
+
+// Manager takes, verifies, prunes and restores backups. A nil Manager
+// backs up nothing.
+type Manager struct {
+	db  Database
+	cfg Config
+
+	mu       sync.Mutex
+	keystore Keystore
+	running  bool // A backup or restore is in progress
+	stop     chan struct{}
+}
+
+// NewManager returns a Manager writing backups of db under cfg.Dir
+func NewManager(db Database, cfg Config) *Manager {
+	def := DefaultConfig()
+	if cfg.Dir == "" {
+		cfg.Dir = def.Dir
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	return &Manager{db: db, cfg: cfg}
+}
+
+// SetKeystore includes ks in backups taken from now on
+func (m *Manager) SetKeystore(ks Keystore) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keystore = ks
+}
+
+// Dir returns the directory of backup id
+func (m *Manager) Dir(id string) string {
+	return filepath.Join(m.cfg.Dir, id)
+}
+
+// Create takes a backup now, verifies it and prunes old ones
+func (m *Manager) Create(ctx context.Context, trigger, createdBy string) (*Backup, error) {
+	if m == nil {
+		return nil, fmt.Errorf("backups not configured")
+	}
+	if err := m.begin(); err != nil {
+		return nil, err
+	}
+	defer m.end()
+
+	b, err := m.create(ctx, trigger, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	m.prune(time.Now())
+	return b, nil
+}
+
+// create writes a backup into a temporary directory and renames it into
+// place once it has been verified, so a failed backup leaves nothing behind
+func (m *Manager) create(ctx context.Context, trigger, createdBy string) (*Backup, error) {
+	now := time.Now().UTC()
+	b := &Backup{
+		ID:           now.Format("20060102T150405Z") + "-" + uuid.New().String()[:8],
+		Trigger:      trigger,
+		DatabaseType: m.db.Type(),
+		CreatedBy:    createdBy,
+		CreatedAt:    now,
+	}
+	version, err := m.db.SchemaVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("read schema version: %w", err)
+	}
+	b.SchemaVersion = version
+
+	if err := os.MkdirAll(m.cfg.Dir, 0700); err != nil {
+		return nil, err
+	}
+	tmp, err := os.MkdirTemp(m.cfg.Dir, ".partial-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	dbFile := databaseFile(b.DatabaseType)
+	if err := m.db.Dump(ctx, filepath.Join(tmp, dbFile)); err != nil {
+		return nil, fmt.Errorf("dump database: %w", err)
+	}
+	if err := b.addFile(tmp, dbFile, KindDatabase); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	ks := m.keystore
+	m.mu.Unlock()
+	if ks != nil {
+		data, err := ks.Export()
+		if err != nil {
+			return nil, fmt.Errorf("export key store: %w", err)
+		}
+		if data != nil {
+			if err := os.WriteFile(filepath.Join(tmp, "keystore.json"), data, 0600); err != nil {
+				return nil, err
+			}
+			if err := b.addFile(tmp, "keystore.json", KindKeystore); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	v := m.verify(ctx, b, tmp)
+	b.Verification = v
+	if !v.OK {
+		return nil, fmt.Errorf("backup failed verification: %s", strings.Join(v.Problems, "; "))
+	}
+	if err := writeManifest(tmp, b); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, m.Dir(b.ID)); err != nil {
+		return nil, err
+	}
+	log.Printf("[Backup] Created %s backup %s (%d bytes, schema version %d)", trigger, b.ID, b.SizeBytes, b.SchemaVersion)
+	return b, nil
+}
+
+// addFile records a file of the backup with its size and checksum
+func (b *Backup) addFile(dir, name, kind string) error {
+	size, sum, err := checksum(filepath.Join(dir, name))
+	if err != nil {
+		return err
+	}
+	b.Files = append(b.Files, File{Name: name, Kind: kind, SizeBytes: size, SHA256: sum})
+	b.SizeBytes += size
+	return nil
+}
+
+// file returns the backup's file of kind, or nil
+func (b *Backup) file(kind string) *File {
+	for i := range b.Files {
+		if b.Files[i].Kind == kind {
+			return &b.Files[i]
+		}
+	}
+	return nil
+}
+
+// List returns every backup, newest first
+func (m *Manager) List() ([]*Backup, error) {
+	if m == nil {
+		return nil, nil
+	}
+	entries, err := os.ReadDir(m.cfg.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var list []*Backup
+	for _, e := range entries {
+		if !e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		b, err := readManifest(m.Dir(e.Name()))
+		if err != nil {
+			log.Printf("[Backup] Skipping %s: %v", e.Name(), err)
+			continue
+		}
+		list = append(list, b)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+// Get returns one backup
+func (m *Manager) Get(id string) (*Backup, error) {
+	if m == nil {
+		return nil, fmt.Errorf("backups not configured")
+	}
+	if !validID(id) {
+		return nil, fmt.Errorf("backup not found: %s", id)
+	}
+	b, err := readManifest(m.Dir(id))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("backup not found: %s", id)
+	}
+	return b, err
+}
+
+// Delete removes a backup
+func (m *Manager) Delete(id string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	return os.RemoveAll(m.Dir(id))
+}
+
+// Verify checks a backup's files against their checksums and the database
+// dump's integrity and schema version, and records the outcome
+func (m *Manager) Verify(ctx context.Context, id string) (*Verification, error) {
+	b, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	v := m.verify(ctx, b, m.Dir(id))
+	b.Verification = v
+	if err := writeManifest(m.Dir(id), b); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// verify checks the backup b stored in dir
+func (m *Manager) verify(ctx context.Context, b *Backup, dir string) *Verification {
+	v := &Verification{VerifiedAt: time.Now().UTC()}
+	for _, f := range b.Files {
+		size, sum, err := checksum(filepath.Join(dir, f.Name))
+		switch {
+		case err != nil:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: %v", f.Name, err))
+		case size != f.SizeBytes || sum != f.SHA256:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: checksum mismatch", f.Name))
+		}
+	}
+
+	switch f := b.file(KindDatabase); {
+	case f == nil:
+		v.Problems = append(v.Problems, "no database dump")
+	case b.DatabaseType != m.db.Type():
+		v.Problems = append(v.Problems, fmt.Sprintf("%s backup cannot be checked against a %s database", b.DatabaseType, m.db.Type()))
+	default:
+		version, err := m.db.VerifyDump(ctx, filepath.Join(dir, f.Name))
+		switch {
+		case err != nil:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s: %v", f.Name, err))
+		case version >= 0 && version != b.SchemaVersion:
+			v.Problems = append(v.Problems, fmt.Sprintf("%s is at schema version %d, manifest says %d", f.Name, version, b.SchemaVersion))
+		}
+	}
+	v.OK = len(v.Problems) == 0
+	return v
+}
+
+// Restore replaces the database and key store with backup id. The backup
+// is verified and its schema version checked first; a backup taken by a
+// newer binary is refused. The current state is backed up before anything
+// is overwritten, and that pre-restore backup is returned. Loom should not
+// be serving while a restore runs.
+func (m *Manager) Restore(ctx context.Context, id string) (*Backup, error) {
+	b, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if b.DatabaseType != m.db.Type() {
+		return nil, fmt.Errorf("invalid backup: %s backup cannot be restored to a %s database", b.DatabaseType, m.db.Type())
+	}
+	latest, err := m.db.LatestSchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if b.SchemaVersion > latest {
+		return nil, fmt.Errorf("invalid backup: schema version %d is newer than this binary's %d", b.SchemaVersion, latest)
+	}
+
+	if err := m.begin(); err != nil {
+		return nil, err
+	}
+	defer m.end()
+
+	v := m.verify(ctx, b, m.Dir(id))
+	if !v.OK {
+		return nil, fmt.Errorf("invalid backup: failed verification: %s", strings.Join(v.Problems, "; "))
+	}
+
+	pre, err := m.create(ctx, TriggerPreRestore, "")
+	if err != nil {
+		return nil, fmt.Errorf("pre-restore backup: %w", err)
+	}
+
+	dump := b.file(KindDatabase)
+	if err := m.db.Restore(ctx, filepath.Join(m.Dir(id), dump.Name)); err != nil {
+		return pre, fmt.Errorf("restore database: %w", err)
+	}
+	if f := b.file(KindKeystore); f != nil {
+		m.mu.Lock()
+		ks := m.keystore
+		m.mu.Unlock()
+		if ks == nil {
+			return pre, fmt.Errorf("backup has a key store but none is configured")
+		}
+		data, err := os.ReadFile(filepath.Join(m.Dir(id), f.Name))
+		if err != nil {
+			return pre, err
+		}
+		if err := ks.Import(data); err != nil {
+			return pre, fmt.Errorf("restore key store: %w", err)
+		}
+	}
+	if err := m.db.Migrate(ctx); err != nil {
+		return pre, fmt.Errorf("migrate restored database: %w", err)
+	}
+	log.Printf("[Backup] Restored backup %s (schema version %d); previous state saved as %s", b.ID, b.SchemaVersion, pre.ID)
+	return pre, nil
+}
+
+// begin marks a backup or restore as running
+func (m *Manager) begin() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.running {
+		return fmt.Errorf("a backup is already running")
+	}
+	m.running = true
+	return nil
+}
+
+func (m *Manager) end() {
+	m.mu.Lock()
+	m.running = false
+	m.mu.Unlock()
+}
+
+// prune removes backups beyond the configured count and age. The newest
+// backup is always kept.
+func (m *Manager) prune(now time.Time) {
+	list, err := m.List()
+	if err != nil {
+		log.Printf("[Backup] Failed to list backups: %v", err)
+		return
+	}
+	cfg := m.config()
+	for i, b := range list {
+		if i == 0 {
+			continue
+		}
+		tooMany := cfg.Keep > 0 && i >= cfg.Keep
+		tooOld := cfg.MaxAge > 0 && now.Sub(b.CreatedAt) > cfg.MaxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.RemoveAll(m.Dir(b.ID)); err != nil {
+			log.Printf("[Backup] Failed to remove %s: %v", b.ID, err)
+			continue
+		}
+		log.Printf("[Backup] Removed expired backup %s", b.ID)
+	}
+}
+
+// SetSchedule changes the interval of scheduled backups and how many are
+// kept, taking effect at the next poll. The directory cannot change.
+func (m *Manager) SetSchedule(interval time.Duration, keep int, maxAge time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg.Interval = interval
+	m.cfg.Keep = keep
+	m.cfg.MaxAge = maxAge
+}
+
+// config returns the current settings
+func (m *Manager) config() Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg
+}
+
+// Start looks for a due scheduled backup every PollInterval until ctx is
+// done or Close is called. Nothing is scheduled while Interval is 0.
+func (m *Manager) Start(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.cfg.PollInterval)
+		defer ticker.Stop()
+		m.RunDue(ctx, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				m.RunDue(ctx, now)
+			}
+		}
+	}()
+}
+
+// Close stops taking scheduled backups
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// RunDue takes a scheduled backup if the newest backup, of any trigger, is
+// at least Interval old at now
+func (m *Manager) RunDue(ctx context.Context, now time.Time) {
+	if m == nil {
+		return
+	}
+	interval := m.config().Interval
+	if interval <= 0 {
+		return
+	}
+	list, err := m.List()
+	if err != nil {
+		log.Printf("[Backup] Failed to list backups: %v", err)
+		return
+	}
+	if len(list) > 0 && now.Sub(list[0].CreatedAt) < interval {
+		return
+	}
+	if _, err := m.Create(ctx, TriggerSchedule, ""); err != nil {
+		log.Printf("[Backup] Scheduled backup failed: %v", err)
+	}
+}
+
+// databaseFile names the dump of a database type
+func databaseFile(dbType string) string {
+	if dbType == "postgres" {
+		return "database.pgdump"
+	}
+	return "database.sqlite"
+}
+
+// validID reports whether id can name a backup directory
+func validID(id string) bool {
+	if id == "" || strings.HasPrefix(id, ".") {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// checksum returns the size and hex SHA-256 of a file
+func checksum(path string) (int64, string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func readManifest(dir string) (*Backup, error) {
+	data, err := os.ReadFile(filepath.Join(dir, manifestName))
+	if err != nil {
+		return nil, err
+	}
+	var b Backup
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &b, nil
+}
+
+func writeManifest(dir string, b *Backup) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, manifestName))
+}
@@ -0,0 +1,271 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeDB keeps its "database" in a file holding the schema version
+type fakeDB struct {
+	path     string
+	latest   int64
+	migrated int
+}
+
+func newFakeDB(t *testing.T, version int64) *fakeDB {
+	t.Helper()
+	db := &fakeDB{path: filepath.Join(t.TempDir(), "live.db"), latest: version}
+	db.write(version)
+	return db
+}
+
+func (f *fakeDB) write(version int64) {
+	_ = os.WriteFile(f.path, []byte(strconv.FormatInt(version, 10)), 0600)
+}
+
+func (f *fakeDB) Type() string { return "sqlite" }
+
+func (f *fakeDB) SchemaVersion(ctx context.Context) (int64, error) {
+	return f.VerifyDump(ctx, f.path)
+}
+
+func (f *fakeDB) LatestSchemaVersion() (int64, error) { return f.latest, nil }
+
+func (f *fakeDB) Dump(ctx context.Context, path string) error {
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func (f *fakeDB) VerifyDump(ctx context.Context, path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	v, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("corrupt")
+	}
+	return v, nil
+}
+
+func (f *fakeDB) Restore(ctx context.Context, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(f.path, data, 0600)
+}
+
+func (f *fakeDB) Migrate(ctx context.Context) error {
+	f.migrated++
+	return nil
+}
+
+type fakeKeystore struct{ data []byte }
+
+func (k *fakeKeystore) Export() ([]byte, error)  { return k.data, nil }
+func (k *fakeKeystore) Import(data []byte) error { k.data = data; return nil }
+
+func newTestManager(t *testing.T, db *fakeDB, cfg Config) *Manager {
+	t.Helper()
+	cfg.Dir = filepath.Join(t.TempDir(), "backups")
+	return NewManager(db, cfg)
+}
+
+func TestCreateListVerify(t *testing.T) {
+	db := newFakeDB(t, 21)
+	m := newTestManager(t, db, Config{})
+	ks := &fakeKeystore{data: []byte(`{"keys":{}}`)}
+	m.SetKeystore(ks)
+	ctx := context.Background()
+
+	b, err := m.Create(ctx, TriggerManual, "admin")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if b.SchemaVersion != 21 || len(b.Files) != 2 || b.Verification == nil || !b.Verification.OK {
+		t.Fatalf("unexpected backup %+v", b)
+	}
+
+	list, err := m.List()
+	if err != nil || len(list) != 1 || list[0].ID != b.ID || list[0].CreatedBy != "admin" {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+	if entries, _ := os.ReadDir(m.cfg.Dir); len(entries) != 1 {
+		t.Errorf("expected only the backup directory, got %d entries", len(entries))
+	}
+
+	if v, err := m.Verify(ctx, b.ID); err != nil || !v.OK {
+		t.Fatalf("Verify = %+v, %v", v, err)
+	}
+
+	// Tampering with the dump is caught by its checksum
+	if err := os.WriteFile(filepath.Join(m.Dir(b.ID), "database.sqlite"), []byte("99"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	v, err := m.Verify(ctx, b.ID)
+	if err != nil || v.OK || !strings.Contains(strings.Join(v.Problems, " "), "checksum mismatch") {
+		t.Fatalf("Verify of tampered backup = %+v, %v", v, err)
+	}
+	if got, _ := m.Get(b.ID); got.Verification == nil || got.Verification.OK {
+		t.Error("failed verification should be recorded in the manifest")
+	}
+
+	for _, id := range []string{"", "../etc", ".partial-1", "nope"} {
+		if _, err := m.Get(id); err == nil || !strings.Contains(err.Error(), "not found") {
+			t.Errorf("Get(%q) = %v, want not found", id, err)
+		}
+	}
+	if err := m.Delete(b.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if list, _ := m.List(); len(list) != 0 {
+		t.Errorf("expected no backups after delete, got %d", len(list))
+	}
+}
+
+func TestCreateFailsVerification(t *testing.T) {
+	db := newFakeDB(t, 3)
+	m := newTestManager(t, db, Config{})
+	_ = os.WriteFile(db.path, []byte("garbage"), 0600)
+
+	if _, err := m.Create(context.Background(), TriggerManual, ""); err == nil {
+		t.Fatal("expected Create to fail")
+	}
+	if entries, _ := os.ReadDir(m.cfg.Dir); len(entries) != 0 {
+		t.Errorf("failed backup left %d entries behind", len(entries))
+	}
+}
+
+func TestRetention(t *testing.T) {
+	db := newFakeDB(t, 1)
+	m := newTestManager(t, db, Config{Keep: 2, MaxAge: time.Hour})
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		b, err := m.Create(ctx, TriggerManual, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, b.ID)
+		time.Sleep(10 * time.Millisecond)
+	}
+	list, _ := m.List()
+	if len(list) != 2 || list[0].ID != ids[2] || list[1].ID != ids[1] {
+		t.Fatalf("expected the two newest backups, got %+v", list)
+	}
+
+	// Past MaxAge everything goes but the newest
+	m.prune(time.Now().Add(2 * time.Hour))
+	if list, _ := m.List(); len(list) != 1 || list[0].ID != ids[2] {
+		t.Fatalf("expected only the newest backup, got %+v", list)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	db := newFakeDB(t, 5)
+	m := newTestManager(t, db, Config{})
+	ks := &fakeKeystore{data: []byte("old keys")}
+	m.SetKeystore(ks)
+	ctx := context.Background()
+
+	b, err := m.Create(ctx, TriggerManual, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.write(6)
+	ks.data = []byte("new keys")
+
+	pre, err := m.Restore(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if v, _ := db.SchemaVersion(ctx); v != 5 || string(ks.data) != "old keys" || db.migrated != 1 {
+		t.Errorf("after restore version %d, keys %q, migrated %d", v, ks.data, db.migrated)
+	}
+	if pre.Trigger != TriggerPreRestore || pre.SchemaVersion != 6 {
+		t.Errorf("unexpected pre-restore backup %+v", pre)
+	}
+}
+
+func TestRestoreRefusesNewerSchema(t *testing.T) {
+	db := newFakeDB(t, 8)
+	m := newTestManager(t, db, Config{})
+	ctx := context.Background()
+	b, err := m.Create(ctx, TriggerManual, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db.latest = 7
+	if _, err := m.Restore(ctx, b.ID); err == nil || !strings.Contains(err.Error(), "newer than this binary") {
+		t.Fatalf("expected newer schema error, got %v", err)
+	}
+	if list, _ := m.List(); len(list) != 1 {
+		t.Error("a refused restore should not take a pre-restore backup")
+	}
+}
+
+func TestRestoreRefusesCorruptBackup(t *testing.T) {
+	db := newFakeDB(t, 2)
+	m := newTestManager(t, db, Config{})
+	ctx := context.Background()
+	b, err := m.Create(ctx, TriggerManual, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = os.WriteFile(filepath.Join(m.Dir(b.ID), "database.sqlite"), []byte("1"), 0600)
+	db.write(4)
+
+	if _, err := m.Restore(ctx, b.ID); err == nil || !strings.Contains(err.Error(), "failed verification") {
+		t.Fatalf("expected verification error, got %v", err)
+	}
+	if v, _ := db.SchemaVersion(ctx); v != 4 {
+		t.Errorf("database changed by a refused restore: version %d", v)
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	db := newFakeDB(t, 1)
+	m := newTestManager(t, db, Config{Interval: time.Hour})
+	ctx := context.Background()
+
+	m.RunDue(ctx, time.Now())
+	list, _ := m.List()
+	if len(list) != 1 || list[0].Trigger != TriggerSchedule {
+		t.Fatalf("expected one scheduled backup, got %+v", list)
+	}
+	m.RunDue(ctx, time.Now().Add(30*time.Minute))
+	if list, _ := m.List(); len(list) != 1 {
+		t.Fatalf("backup taken before the interval passed")
+	}
+	m.RunDue(ctx, time.Now().Add(2*time.Hour))
+	if list, _ := m.List(); len(list) != 2 {
+		t.Fatalf("expected a second scheduled backup, got %d", len(list))
+	}
+
+	// Turning the schedule off stops scheduled backups; keep applies at once
+	m.SetSchedule(0, 1, 0)
+	m.RunDue(ctx, time.Now().Add(4*time.Hour))
+	m.prune(time.Now())
+	if list, _ := m.List(); len(list) != 1 {
+		t.Fatalf("expected one backup after rescheduling, got %d", len(list))
+	}
+
+	var nilManager *Manager
+	nilManager.RunDue(ctx, time.Now())
+	nilManager.Close()
+	if list, err := nilManager.List(); list != nil || err != nil {
+		t.Error("nil manager should list nothing")
+	}
+}
@@ -3,6 +3,7 @@ package beads
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -19,6 +20,10 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
+// ErrBeadNotFound is returned by GetBead for a bead neither loaded nor
+// known to bd
+var ErrBeadNotFound = errors.New("bead not found")
+
 // Manager integrates with the bd (beads) CLI tool
 type Manager struct {
 	bdPath          string
@@ -30,6 +35,8 @@ type Manager struct {
 	nextID          int               // For generating IDs when bd CLI is not available
 	projectPrefixes map[string]string // Project ID -> bead prefix (e.g., "loom-self" -> "ac")
 	projectNextIDs  map[string]int    // Per-project next ID counter
+	orgOf           func(projectID string) string
+	onChange        func(beadID string)
 }
 
 // NewManager creates a new beads manager
@@ -62,6 +69,30 @@ func (m *Manager) Reset() {
 	m.projectNextIDs = make(map[string]int)
 }
 
+// SetOrgLookup sets how the organization of a project is found, which
+// the "org_id" filter of ListBeads needs
+func (m *Manager) SetOrgLookup(lookup func(projectID string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.orgOf = lookup
+}
+
+// SetChangeHook sets a function called with the ID of every bead that is
+// created, updated or loaded. It is called with the manager locked, so it
+// must not call back into the manager.
+func (m *Manager) SetChangeHook(hook func(beadID string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = hook
+}
+
+// changed reports a bead change to the change hook; callers hold m.mu
+func (m *Manager) changed(beadID string) {
+	if m.onChange != nil {
+		m.onChange(beadID)
+	}
+}
+
 // SetBeadsPath sets the path to the beads directory
 func (m *Manager) SetBeadsPath(path string) {
 	m.beadsPath = path
@@ -214,6 +245,7 @@ func (m *Manager) CreateBead(title, description string, priority models.BeadPrio
 	m.beads[beadID] = bead
 	m.workGraph.Beads[beadID] = bead
 	m.workGraph.UpdatedAt = time.Now()
+	m.changed(beadID)
 
 	// Save to filesystem only when not using bd CLI
 	if !usedBD {
@@ -332,6 +364,7 @@ func (m *Manager) UpdateBead(id string, updates map[string]interface{}) error {
 
 	bead.UpdatedAt = time.Now()
 	m.workGraph.UpdatedAt = time.Now()
+	m.changed(bead.ID)
 
 	if assignedUpdated && previousAssigned != bead.AssignedTo {
 		observability.Info("bead.assignment_updated", map[string]interface{}{
@@ -379,6 +412,7 @@ func (m *Manager) ClaimBead(beadID, agentID string) error {
 	bead.AssignedTo = agentID
 	bead.Status = models.BeadStatusInProgress
 	bead.UpdatedAt = time.Now()
+	m.changed(bead.ID)
 
 	observability.Info("bead.claim", map[string]interface{}{
 		"agent_id":   agentID,
@@ -604,6 +638,9 @@ func beadsRootDir(beadsPath string) string {
 }
 
 func (m *Manager) fetchBeadFromBD(id string) (*models.Bead, error) {
+	if m.bdPath == "" {
+		return nil, fmt.Errorf("%w: %s", ErrBeadNotFound, id)
+	}
 	// Execute: bd show <id> --json
 	cmd := exec.Command(m.bdPath, "show", id, "--json")
 	if dir := beadsRootDir(m.beadsPath); dir != "" {
@@ -611,6 +648,9 @@ func (m *Manager) fetchBeadFromBD(id string) (*models.Bead, error) {
 	}
 	output, err := cmd.CombinedOutput()
 	if err != nil {
+		if strings.Contains(strings.ToLower(string(output)), "not found") {
+			return nil, fmt.Errorf("%w: %s", ErrBeadNotFound, id)
+		}
 		return nil, fmt.Errorf("failed to fetch bead: %w", err)
 	}
 
@@ -641,6 +681,12 @@ func (m *Manager) matchesFilters(bead *models.Bead, filters map[string]interface
 		}
 	}
 
+	if orgID, ok := filters["org_id"].(string); ok {
+		if m.orgOf == nil || m.orgOf(bead.ProjectID) != orgID {
+			return false
+		}
+	}
+
 	if assignedTo, ok := filters["assigned_to"]; ok {
 		switch value := assignedTo.(type) {
 		case string:
@@ -722,6 +768,7 @@ func (m *Manager) LoadBeadsFromFilesystem(projectID, beadsPath string) error {
 		m.beads[bead.ID] = &bead
 		m.workGraph.Beads[bead.ID] = &bead
 		m.beadFiles[bead.ID] = beadPath
+		m.changed(bead.ID)
 		loadedCount++
 	}
 
@@ -830,6 +877,7 @@ func (m *Manager) loadBeadsFromBD(projectID, beadsPath string) error {
 
 		m.beads[bead.ID] = bead
 		m.workGraph.Beads[bead.ID] = bead
+		m.changed(bead.ID)
 	}
 
 	m.workGraph.UpdatedAt = time.Now()
@@ -2,6 +2,7 @@ package beads
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -206,6 +207,24 @@ func TestManager_GetBead(t *testing.T) {
 	if retrieved.ID != bead.ID {
 		t.Errorf("GetBead().ID = %q, want %q", retrieved.ID, bead.ID)
 	}
+
+	if _, err := manager.GetBead("bd-missing"); !errors.Is(err, ErrBeadNotFound) {
+		t.Errorf("GetBead(missing) error = %v, want ErrBeadNotFound", err)
+	}
+
+	// bd reporting the bead missing is not found; bd failing otherwise is
+	// not the bead being gone
+	dir := t.TempDir()
+	bd := filepath.Join(dir, "bd")
+	if err := os.WriteFile(bd, []byte("#!/bin/sh\necho \"Error: issue $2 not found\"\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewManager(bd).GetBead("bd-missing"); !errors.Is(err, ErrBeadNotFound) {
+		t.Errorf("GetBead(missing) with bd error = %v, want ErrBeadNotFound", err)
+	}
+	if _, err := NewManager(filepath.Join(dir, "no-such-bd")).GetBead("bd-missing"); err == nil || errors.Is(err, ErrBeadNotFound) {
+		t.Errorf("GetBead with bd unavailable error = %v, want another error", err)
+	}
 }
 
 // TestManager_ListBeads tests listing beads
@@ -272,6 +291,71 @@ func TestManager_ListBeads(t *testing.T) {
 	_ = bead3 // Silence unused warning
 }
 
+// TestManager_ListBeads_OrgFilter tests scoping a listing to an organization
+func TestManager_ListBeads_OrgFilter(t *testing.T) {
+	manager := NewManager("")
+	manager.CreateBead("Bead 1", "Desc 1", models.BeadPriorityP1, "task", "project1")
+	manager.CreateBead("Bead 2", "Desc 2", models.BeadPriorityP2, "task", "project2")
+
+	filters := map[string]interface{}{"org_id": "acme"}
+	if got, _ := manager.ListBeads(filters); len(got) != 0 {
+		t.Errorf("ListBeads(org) without an org lookup returned %d beads, want 0", len(got))
+	}
+
+	manager.SetOrgLookup(func(projectID string) string {
+		if projectID == "project1" {
+			return "acme"
+		}
+		return "other"
+	})
+	got, err := manager.ListBeads(filters)
+	if err != nil {
+		t.Fatalf("ListBeads(org) error = %v", err)
+	}
+	if len(got) != 1 || got[0].ProjectID != "project1" {
+		t.Errorf("ListBeads(org) = %v, want the project1 bead", got)
+	}
+}
+
+// TestManager_ListBeadsPage tests walking a sorted listing page by page
+func TestManager_ListBeadsPage(t *testing.T) {
+	manager := NewManager("")
+	manager.SetBeadsPath(t.TempDir())
+	for _, p := range []models.BeadPriority{models.BeadPriorityP2, models.BeadPriorityP1, models.BeadPriorityP2, models.BeadPriorityP1, models.BeadPriorityP2} {
+		manager.CreateBead("Bead", "Desc", p, "task", "project1")
+	}
+
+	q := PageQuery{Sort: "priority", Desc: true, Limit: 2}
+	seen := map[string]bool{}
+	var last *models.Bead
+	for page := 0; page < 5; page++ {
+		beads, total, err := manager.ListBeadsPage(nil, q)
+		if err != nil {
+			t.Fatalf("ListBeadsPage() error = %v", err)
+		}
+		if total != 5 {
+			t.Errorf("ListBeadsPage() total = %d, want 5", total)
+		}
+		if len(beads) == 0 {
+			break
+		}
+		for _, b := range beads {
+			if seen[b.ID] {
+				t.Errorf("bead %s listed twice", b.ID)
+			}
+			if last != nil && b.Priority > last.Priority {
+				t.Errorf("bead %s (P%d) listed after P%d", b.ID, b.Priority, last.Priority)
+			}
+			seen[b.ID] = true
+			last = b
+		}
+		q.After = SortKey(last, q.Sort)
+	}
+	if len(seen) != 5 {
+		t.Errorf("paging listed %d beads, want 5", len(seen))
+	}
+}
+
 // TestManager_UpdateBead tests updating a bead
 func TestManager_UpdateBead(t *testing.T) {
 	manager := NewManager("")
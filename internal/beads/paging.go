@@ -0,0 +1,98 @@
+package beads
+
+import (
+	"cmp"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// PageQuery selects a page of ListBeadsPage
+type PageQuery struct {
+	Sort   string        // priority, created_at, updated_at, title or status
+	Desc   bool          // descending order
+	After  []interface{} // SortKey of the last bead of the previous page; nil for the first page
+	Offset int           // beads to skip when After is nil
+	Limit  int           // 0 returns every bead from the start of the page
+}
+
+// SortKey returns the position of a bead in a list sorted by field: the
+// value of the field, then the bead ID so that no two beads tie
+func SortKey(b *models.Bead, field string) []interface{} {
+	switch field {
+	case "priority":
+		return []interface{}{int(b.Priority), b.ID}
+	case "created_at":
+		return []interface{}{b.CreatedAt, b.ID}
+	case "updated_at":
+		return []interface{}{b.UpdatedAt, b.ID}
+	case "title":
+		return []interface{}{b.Title, b.ID}
+	case "status":
+		return []interface{}{string(b.Status), b.ID}
+	}
+	return []interface{}{b.ID}
+}
+
+// ListBeadsPage returns one page of the beads matching filters, in the
+// order q asks for, and how many beads match in all
+func (m *Manager) ListBeadsPage(filters map[string]interface{}, q PageQuery) ([]*models.Bead, int, error) {
+	beads, err := m.ListBeads(filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortsBefore := func(a, b []interface{}) bool {
+		if q.Desc {
+			return compareKeys(a, b) > 0
+		}
+		return compareKeys(a, b) < 0
+	}
+	sort.Slice(beads, func(i, j int) bool {
+		return sortsBefore(SortKey(beads[i], q.Sort), SortKey(beads[j], q.Sort))
+	})
+
+	total := len(beads)
+	if q.After != nil {
+		start := sort.Search(len(beads), func(i int) bool {
+			return sortsBefore(q.After, SortKey(beads[i], q.Sort))
+		})
+		beads = beads[start:]
+	} else {
+		beads = beads[min(q.Offset, total):]
+	}
+	if q.Limit > 0 && q.Limit < len(beads) {
+		beads = beads[:q.Limit]
+	}
+	return beads, total, nil
+}
+
+// compareKeys orders two sort keys value by value
+func compareKeys(a, b []interface{}) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+func compareValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int:
+		if b, ok := b.(int); ok {
+			return cmp.Compare(a, b)
+		}
+	case string:
+		if b, ok := b.(string); ok {
+			return cmp.Compare(a, b)
+		}
+	case time.Time:
+		if b, ok := b.(time.Time); ok {
+			return a.Compare(b)
+		}
+	}
+	return cmp.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
@@ -0,0 +1,463 @@
+// Package benchmark measures providers on a curated set of golden tasks.
+// A run sends every task, from small to complex, to each active provider,
+// checks the answers against the tasks' expectations, and records per tier
+// how often each provider succeeded, how fast it answered and what it
+// cost. Runs happen on demand or on a schedule, and whoever listens gets
+// the measurements to feed into provider scoring.
+package benchmark
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Run triggers
+const (
+	TriggerManual   = "manual"
+	TriggerSchedule = "schedule"
+)
+
+// maxResponseChars bounds the answer kept with each result
+const maxResponseChars = 2000
+
+// Result is one task answered by one provider
+type Result struct {
+	TaskID           string   `json:"task_id"`
+	Tier             string   `json:"tier"`
+	ProviderID       string   `json:"provider_id"`
+	Model            string   `json:"model,omitempty"`
+	Passed           bool     `json:"passed"`
+	Score            float64  `json:"score"` // Fraction of expectations met, 0 on error
+	Failures         []string `json:"failures,omitempty"`
+	Response         string   `json:"response,omitempty"`
+	LatencyMs        int64    `json:"latency_ms"`
+	PromptTokens     int      `json:"prompt_tokens"`
+	CompletionTokens int      `json:"completion_tokens"`
+	CostUSD          float64  `json:"cost_usd"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// TierStats sums up one provider's results on one tier
+type TierStats struct {
+	Tier         string  `json:"tier"`
+	Tasks        int     `json:"tasks"`
+	Passed       int     `json:"passed"`
+	SuccessRate  float64 `json:"success_rate"`   // Passed / Tasks
+	AvgLatencyMs float64 `json:"avg_latency_ms"` // Over the tasks that got an answer
+	CostUSD      float64 `json:"cost_usd"`
+}
+
+// ProviderSummary sums up one provider's results in a run
+type ProviderSummary struct {
+	ProviderID    string      `json:"provider_id"`
+	Model         string      `json:"model,omitempty"`
+	Tiers         []TierStats `json:"tiers"`
+	Tasks         int         `json:"tasks"`
+	Passed        int         `json:"passed"`
+	Answered      int         `json:"answered"` // Tasks that got an answer, right or wrong
+	SuccessRate   float64     `json:"success_rate"`
+	AvgLatencyMs  float64     `json:"avg_latency_ms"`
+	CostUSD       float64     `json:"cost_usd"`
+	CostPerMToken float64     `json:"cost_per_mtoken"` // Measured; 0 when the provider is not priced
+}
+
+// Run is one benchmark of the active providers
+type Run struct {
+	ID         string            `json:"id"`
+	Trigger    string            `json:"trigger"`
+	CreatedBy  string            `json:"created_by,omitempty"`
+	Tasks      int               `json:"tasks"`
+	Passed     int               `json:"passed"` // Results, not tasks: a task counts once per provider
+	Failed     int               `json:"failed"`
+	Providers  []ProviderSummary `json:"providers"`
+	Results    []Result          `json:"results"`
+	StartedAt  time.Time         `json:"started_at"`
+	FinishedAt time.Time         `json:"finished_at"`
+}
+
+// Store persists runs
+type Store interface {
+	// SaveBenchmarkRun inserts a run
+	SaveBenchmarkRun(r *Run) error
+	// GetBenchmarkRun returns a run, or nil if unknown
+	GetBenchmarkRun(id string) (*Run, error)
+	// ListBenchmarkRuns returns the most recent runs, newest first; limit
+	// 0 means no limit
+	ListBenchmarkRuns(limit int) ([]*Run, error)
+}
+
+// Target is a provider tasks can run on
+type Target struct {
+	ProviderID string
+	Model      string
+}
+
+// Completion is a provider's answer to a task and what it took
+type Completion struct {
+	Response         string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+	CostUSD          float64
+}
+
+// Completer sends tasks to providers
+type Completer interface {
+	// Targets returns the active providers
+	Targets() []Target
+	// Complete answers prompt on a provider
+	Complete(ctx context.Context, providerID, systemPrompt, prompt string) (Completion, error)
+}
+
+// Config tunes the benchmark
+type Config struct {
+	Interval     time.Duration // Between scheduled runs; 0 runs only on demand
+	Timeout      time.Duration // Per task
+	ProviderIDs  []string      // Providers scheduled runs cover; empty covers every active provider
+	PollInterval time.Duration // How often a scheduled run is looked for
+}
+
+// DefaultConfig gives each task two minutes and runs only on demand
+func DefaultConfig() Config {
+	return Config{Timeout: 2 * time.Minute, PollInterval: time.Minute}
+}
+
+// Manager runs benchmarks. Start and Close do nothing on a nil Manager.
+type Manager struct {
+	store     Store
+	completer Completer
+	cfg       Config
+	tasks     []Task
+
+	mu      sync.Mutex
+	running bool
+	onRun   func(*Run)
+	stop    chan struct{}
+}
+
+// NewManager creates a manager backed by store that runs the default
+// tasks through completer
+func NewManager(store Store, completer Completer, cfg Config) *Manager {
+	if store == nil || completer == nil {
+		return nil
+	}
+	def := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = def.PollInterval
+	}
+	return &Manager{store: store, completer: completer, cfg: cfg, tasks: DefaultTasks()}
+}
+
+// OnRun registers the function told about every recorded run
+func (m *Manager) OnRun(fn func(*Run)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRun = fn
+}
+
+// Tasks returns the tasks providers are benchmarked on
+func (m *Manager) Tasks() []Task {
+	return m.tasks
+}
+
+// GetRun returns a run
+func (m *Manager) GetRun(id string) (*Run, error) {
+	r, err := m.store.GetBenchmarkRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil {
+		return nil, fmt.Errorf("benchmark run not found: %s", id)
+	}
+	return r, nil
+}
+
+// Runs returns the most recent runs, newest first
+func (m *Manager) Runs(limit int) ([]*Run, error) {
+	return m.store.ListBenchmarkRuns(limit)
+}
+
+// Run benchmarks providerIDs, or every active provider when empty, now and
+// records the run. Providers run in parallel, their tasks one after the
+// other, so latencies are not skewed by a provider's own queue.
+func (m *Manager) Run(ctx context.Context, trigger string, providerIDs []string, createdBy string) (*Run, error) {
+	targets, err := m.targets(providerIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if m.running {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("a benchmark is already running")
+	}
+	m.running = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.running = false
+		m.mu.Unlock()
+	}()
+
+	run := &Run{
+		ID:        uuid.New().String(),
+		Trigger:   trigger,
+		CreatedBy: createdBy,
+		Tasks:     len(m.tasks),
+		StartedAt: time.Now().UTC(),
+	}
+	results := make([][]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(i int, t Target) {
+			defer wg.Done()
+			for _, task := range m.tasks {
+				if ctx.Err() != nil {
+					return
+				}
+				results[i] = append(results[i], m.runTask(ctx, task, t))
+			}
+		}(i, t)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	for i, t := range targets {
+		run.Results = append(run.Results, results[i]...)
+		run.Providers = append(run.Providers, Summarize(t, results[i]))
+		for _, res := range results[i] {
+			if res.Passed {
+				run.Passed++
+			} else {
+				run.Failed++
+			}
+		}
+	}
+	run.FinishedAt = time.Now().UTC()
+	if err := m.store.SaveBenchmarkRun(run); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	notify := m.onRun
+	m.mu.Unlock()
+	if notify != nil {
+		notify(run)
+	}
+	return run, nil
+}
+
+// targets returns the active providers among providerIDs, or every active
+// provider when it is empty
+func (m *Manager) targets(providerIDs []string) ([]Target, error) {
+	active := m.completer.Targets()
+	if len(providerIDs) == 0 {
+		if len(active) == 0 {
+			return nil, fmt.Errorf("invalid benchmark: no active providers")
+		}
+		return active, nil
+	}
+	byID := make(map[string]Target, len(active))
+	for _, t := range active {
+		byID[t.ProviderID] = t
+	}
+	var targets []Target
+	var missing []string
+	for _, id := range providerIDs {
+		t, ok := byID[id]
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+		targets = append(targets, t)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("invalid benchmark: providers not active: %s", strings.Join(missing, ", "))
+	}
+	return targets, nil
+}
+
+// runTask answers one task on one provider and checks the answer
+func (m *Manager) runTask(ctx context.Context, task Task, t Target) Result {
+	res := Result{TaskID: task.ID, Tier: task.Tier, ProviderID: t.ProviderID, Model: t.Model}
+	systemPrompt := task.SystemPrompt
+	if systemPrompt == "" {
+		systemPrompt = benchmarkSystemPrompt
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	c, err := m.completer.Complete(cctx, t.ProviderID, systemPrompt, task.Prompt)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if c.Model != "" {
+		res.Model = c.Model
+	}
+	res.PromptTokens = c.PromptTokens
+	res.CompletionTokens = c.CompletionTokens
+	res.CostUSD = c.CostUSD
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(c.Response) > maxResponseChars {
+		res.Response = c.Response[:maxResponseChars]
+	} else {
+		res.Response = c.Response
+	}
+
+	met := 0
+	for _, e := range task.Expectations {
+		if failure := e.Check(c.Response, res.LatencyMs); failure != "" {
+			res.Failures = append(res.Failures, failure)
+			continue
+		}
+		met++
+	}
+	res.Score = float64(met) / float64(len(task.Expectations))
+	res.Passed = met == len(task.Expectations)
+	return res
+}
+
+// Summarize sums up a provider's results per tier and overall. Latency
+// counts only answered tasks, since failed requests often return early.
+func Summarize(t Target, results []Result) ProviderSummary {
+	s := ProviderSummary{ProviderID: t.ProviderID, Model: t.Model}
+	byTier := make(map[string]*TierStats)
+	answered := make(map[string]int)
+	var latency int64
+	var tokens int
+	for _, res := range results {
+		if res.Model != "" {
+			s.Model = res.Model
+		}
+		ts, ok := byTier[res.Tier]
+		if !ok {
+			ts = &TierStats{Tier: res.Tier}
+			byTier[res.Tier] = ts
+		}
+		ts.Tasks++
+		ts.CostUSD += res.CostUSD
+		s.Tasks++
+		s.CostUSD += res.CostUSD
+		if res.Passed {
+			ts.Passed++
+			s.Passed++
+		}
+		if res.Error == "" {
+			ts.AvgLatencyMs += float64(res.LatencyMs)
+			answered[res.Tier]++
+			s.Answered++
+			latency += res.LatencyMs
+			tokens += res.PromptTokens + res.CompletionTokens
+		}
+	}
+
+	for _, ts := range byTier {
+		if n := answered[ts.Tier]; n > 0 {
+			ts.AvgLatencyMs /= float64(n)
+		}
+		ts.SuccessRate = float64(ts.Passed) / float64(ts.Tasks)
+		s.Tiers = append(s.Tiers, *ts)
+	}
+	sort.Slice(s.Tiers, func(i, j int) bool { return tierOrder(s.Tiers[i].Tier) < tierOrder(s.Tiers[j].Tier) })
+
+	if s.Tasks > 0 {
+		s.SuccessRate = float64(s.Passed) / float64(s.Tasks)
+	}
+	if s.Answered > 0 {
+		s.AvgLatencyMs = float64(latency) / float64(s.Answered)
+	}
+	if tokens > 0 {
+		s.CostPerMToken = s.CostUSD / float64(tokens) * 1e6
+	}
+	return s
+}
+
+// tierOrder sorts the known tiers first, smallest first
+func tierOrder(tier string) int {
+	for i, t := range tiers {
+		if t == tier {
+			return i
+		}
+	}
+	return len(tiers)
+}
+
+// Start runs scheduled benchmarks until ctx ends or Close is called
+func (m *Manager) Start(ctx context.Context) {
+	if m == nil || m.cfg.Interval <= 0 {
+		return
+	}
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				m.RunDue(ctx, now)
+			}
+		}
+	}()
+}
+
+// Close stops running scheduled benchmarks
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// RunDue runs a scheduled benchmark if the newest run, of any trigger, is
+// at least Interval old at now
+func (m *Manager) RunDue(ctx context.Context, now time.Time) {
+	if m == nil || m.cfg.Interval <= 0 {
+		return
+	}
+	list, err := m.store.ListBenchmarkRuns(1)
+	if err != nil {
+		log.Printf("[Benchmark] Failed to list runs: %v", err)
+		return
+	}
+	if len(list) > 0 && now.Sub(list[0].StartedAt) < m.cfg.Interval {
+		return
+	}
+	run, err := m.Run(ctx, TriggerSchedule, m.cfg.ProviderIDs, "")
+	if err != nil {
+		log.Printf("[Benchmark] Scheduled run failed: %v", err)
+		return
+	}
+	log.Printf("[Benchmark] Scheduled run %s: %d passed, %d failed across %d provider(s)", run.ID, run.Passed, run.Failed, len(run.Providers))
+}
@@ -0,0 +1,160 @@
+package benchmark_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "benchmark.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// correctAnswers pass every default task
+var correctAnswers = map[string]string{
+	"What is 17":    "391",
+	"Extract":       `{"name": "Ada Lovelace", "age": 36}`,
+	"Write a Go":    "func Reverse(s string) string {\n\tr := []rune(s)\n\treturn string(r)\n}",
+	"This Go loop":  "for i := 0; i < len(xs); i++ {",
+	"Design":        `{"algorithm": "token bucket", "storage": "redis", "steps": ["a", "b", "c"], "failure_mode": "fail open"}`,
+	"Given the tab": "SELECT c.region, SUM(o.total) FROM orders o JOIN customers c ON c.id = o.customer_id WHERE o.created_at >= '2025-01-01' GROUP BY c.region ORDER BY 2 DESC",
+}
+
+// fakeCompleter answers correctly on "good", wrongly on "bad" and fails on
+// "down"
+type fakeCompleter struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (f *fakeCompleter) Targets() []benchmark.Target {
+	return []benchmark.Target{{ProviderID: "good", Model: "g1"}, {ProviderID: "bad", Model: "b1"}, {ProviderID: "down"}}
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, providerID, systemPrompt, prompt string) (benchmark.Completion, error) {
+	f.mu.Lock()
+	f.calls++
+	f.mu.Unlock()
+	switch providerID {
+	case "down":
+		return benchmark.Completion{}, fmt.Errorf("connection refused")
+	case "bad":
+		return benchmark.Completion{Response: "I don't know", PromptTokens: 100, CompletionTokens: 100, CostUSD: 0.002}, nil
+	}
+	for prefix, answer := range correctAnswers {
+		if strings.HasPrefix(prompt, prefix) {
+			return benchmark.Completion{Response: answer, PromptTokens: 400, CompletionTokens: 100}, nil
+		}
+	}
+	return benchmark.Completion{}, fmt.Errorf("unexpected prompt %q", prompt)
+}
+
+func TestDefaultTasks(t *testing.T) {
+	seen := make(map[string]bool)
+	perTier := make(map[string]int)
+	for _, task := range benchmark.DefaultTasks() {
+		if seen[task.ID] {
+			t.Errorf("task %s is listed twice", task.ID)
+		}
+		seen[task.ID] = true
+		perTier[task.Tier]++
+		if len(task.Expectations) == 0 {
+			t.Errorf("task %s checks nothing", task.ID)
+		}
+		for i := range task.Expectations {
+			if err := task.Expectations[i].Validate(); err != nil {
+				t.Errorf("task %s: %v", task.ID, err)
+			}
+		}
+	}
+	for _, tier := range []string{benchmark.TierSmall, benchmark.TierMedium, benchmark.TierComplex} {
+		if perTier[tier] == 0 {
+			t.Errorf("no %s tasks", tier)
+		}
+	}
+}
+
+func TestRun_SummarizesPerTier(t *testing.T) {
+	db := newTestDB(t)
+	mgr := benchmark.NewManager(db, &fakeCompleter{}, benchmark.Config{})
+
+	var notified *benchmark.Run
+	mgr.OnRun(func(r *benchmark.Run) { notified = r })
+
+	run, err := mgr.Run(context.Background(), benchmark.TriggerManual, nil, "u-1")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	tasks := len(benchmark.DefaultTasks())
+	if len(run.Results) != 3*tasks || run.Passed != tasks || run.Failed != 2*tasks || notified != run {
+		t.Fatalf("unexpected run: %d results, %d passed, %d failed", len(run.Results), run.Passed, run.Failed)
+	}
+
+	good, bad, down := run.Providers[0], run.Providers[1], run.Providers[2]
+	if good.ProviderID != "good" || good.SuccessRate != 1 || len(good.Tiers) != 3 || good.Tiers[0].Tier != benchmark.TierSmall {
+		t.Errorf("unexpected summary of good: %+v", good)
+	}
+	for _, res := range run.Results {
+		if res.ProviderID == "good" && !res.Passed {
+			t.Errorf("expected %s to pass, failures: %v", res.TaskID, res.Failures)
+		}
+	}
+	if bad.SuccessRate != 0 || bad.Answered != tasks || bad.CostPerMToken < 9.99 || bad.CostPerMToken > 10.01 {
+		t.Errorf("unexpected summary of bad: %+v", bad)
+	}
+	if down.Answered != 0 || down.AvgLatencyMs != 0 {
+		t.Errorf("expected failed requests left out of latency: %+v", down)
+	}
+
+	stored, err := mgr.GetRun(run.ID)
+	if err != nil || len(stored.Providers) != 3 {
+		t.Errorf("GetRun = %+v, %v", stored, err)
+	}
+	if _, err := mgr.GetRun("nope"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected an unknown run to be not found, got %v", err)
+	}
+}
+
+func TestRun_SelectedProviders(t *testing.T) {
+	mgr := benchmark.NewManager(newTestDB(t), &fakeCompleter{}, benchmark.Config{})
+	run, err := mgr.Run(context.Background(), benchmark.TriggerManual, []string{"good"}, "")
+	if err != nil || len(run.Providers) != 1 {
+		t.Fatalf("Run = %+v, %v", run, err)
+	}
+	if _, err := mgr.Run(context.Background(), benchmark.TriggerManual, []string{"good", "gone"}, ""); err == nil || !strings.HasPrefix(err.Error(), "invalid") {
+		t.Errorf("expected inactive providers to be refused, got %v", err)
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	completer := &fakeCompleter{}
+	mgr := benchmark.NewManager(newTestDB(t), completer, benchmark.Config{Interval: time.Hour, ProviderIDs: []string{"good"}})
+	now := time.Now()
+
+	mgr.RunDue(context.Background(), now)
+	runs, _ := mgr.Runs(0)
+	if len(runs) != 1 || runs[0].Trigger != benchmark.TriggerSchedule {
+		t.Fatalf("expected a scheduled run, got %+v", runs)
+	}
+	mgr.RunDue(context.Background(), now.Add(30*time.Minute))
+	if runs, _ := mgr.Runs(0); len(runs) != 1 {
+		t.Errorf("expected no run before the interval, got %d runs", len(runs))
+	}
+	mgr.RunDue(context.Background(), now.Add(2*time.Hour))
+	if runs, _ := mgr.Runs(0); len(runs) != 2 {
+		t.Errorf("expected a run after the interval, got %d runs", len(runs))
+	}
+}
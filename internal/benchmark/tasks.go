@@ -0,0 +1,99 @@
+package benchmark
+
+import "github.com/jordanhubbard/loom/internal/goldenprompts"
+
+// Task tiers, from what any model should answer to what only capable ones
+// get right
+const (
+	TierSmall   = "small"
+	TierMedium  = "medium"
+	TierComplex = "complex"
+)
+
+// tiers lists the tiers in the order summaries report them
+var tiers = []string{TierSmall, TierMedium, TierComplex}
+
+// Task is one golden task: a prompt with an answer that can be checked
+// without a judge
+type Task struct {
+	ID           string                      `json:"id"`
+	Name         string                      `json:"name"`
+	Tier         string                      `json:"tier"`
+	SystemPrompt string                      `json:"system_prompt,omitempty"`
+	Prompt       string                      `json:"prompt"`
+	Expectations []goldenprompts.Expectation `json:"expectations"`
+}
+
+// benchmarkSystemPrompt keeps answers short and free of commentary so the
+// expectations can check them
+const benchmarkSystemPrompt = "You are being benchmarked. Follow the requested output format exactly and add no commentary."
+
+// DefaultTasks is the curated set every provider is benchmarked on: two
+// tasks per tier, each checked by its expectations
+func DefaultTasks() []Task {
+	return []Task{
+		{
+			ID: "arithmetic", Name: "Multiply two numbers", Tier: TierSmall,
+			Prompt: "What is 17 * 23? Reply with the number only.",
+			Expectations: []goldenprompts.Expectation{
+				{Type: goldenprompts.ExpectRegex, Value: `^\s*391\s*\.?\s*$`},
+			},
+		},
+		{
+			ID: "extract_json", Name: "Extract fields as JSON", Tier: TierSmall,
+			Prompt: "Extract the person's name and age from this sentence as a JSON object with the keys name and age: " +
+				"\"Ada Lovelace was 36 when she died.\" Reply with the JSON only.",
+			Expectations: []goldenprompts.Expectation{
+				{Type: goldenprompts.ExpectJSON},
+				{Type: goldenprompts.ExpectContains, Value: "Ada Lovelace"},
+				{Type: goldenprompts.ExpectRegex, Value: `"age"\s*:\s*36\b`},
+			},
+		},
+		{
+			ID: "go_function", Name: "Write a Go function", Tier: TierMedium,
+			Prompt: "Write a Go function with the signature func Reverse(s string) string that reverses s by Unicode code point, " +
+				"so multi-byte characters stay intact. Reply with the function only.",
+			Expectations: []goldenprompts.Expectation{
+				{Type: goldenprompts.ExpectContains, Value: "func Reverse(s string) string"},
+				{Type: goldenprompts.ExpectContains, Value: "[]rune"},
+				{Type: goldenprompts.ExpectNotContains, Value: "package main"},
+			},
+		},
+		{
+			ID: "off_by_one", Name: "Fix an off-by-one bug", Tier: TierMedium,
+			Prompt: "This Go loop panics with an index out of range:\n\n" +
+				"func Sum(xs []int) int {\n\ttotal := 0\n\tfor i := 0; i <= len(xs); i++ {\n\t\ttotal += xs[i]\n\t}\n\treturn total\n}\n\n" +
+				"Reply with the corrected for statement line only.",
+			Expectations: []goldenprompts.Expectation{
+				{Type: goldenprompts.ExpectRegex, Value: `i\s*<\s*len\(xs\)`},
+				{Type: goldenprompts.ExpectNotContains, Value: "<= len"},
+				{Type: goldenprompts.ExpectMaxLength, Value: "200"},
+			},
+		},
+		{
+			ID: "rate_limiter_design", Name: "Design a rate limiter", Tier: TierComplex,
+			Prompt: "Design per-tenant rate limiting for an HTTP API served by 20 stateless replicas to 10,000 tenants, each with " +
+				"its own requests-per-minute quota. Reply with a JSON object with the keys algorithm (string), storage (string), " +
+				"steps (an array of at least three strings) and failure_mode (what happens when the storage is unreachable).",
+			Expectations: []goldenprompts.Expectation{
+				{Type: goldenprompts.ExpectJSON},
+				{Type: goldenprompts.ExpectRegex, Value: `(?i)token bucket|leaky bucket|sliding window|fixed window|GCRA`},
+				{Type: goldenprompts.ExpectRegex, Value: `"steps"\s*:\s*\[\s*"[^"]+"\s*,\s*"[^"]+"\s*,\s*"[^"]+"`},
+				{Type: goldenprompts.ExpectContains, Value: "\"failure_mode\""},
+			},
+		},
+		{
+			ID: "sql_report", Name: "Write a reporting query", Tier: TierComplex,
+			Prompt: "Given the tables orders(id, customer_id, total, created_at) and customers(id, region), write one SQL query " +
+				"that returns each region's total revenue from orders created in 2025, highest revenue first, leaving out " +
+				"regions with no orders that year. Reply with the SQL only.",
+			Expectations: []goldenprompts.Expectation{
+				{Type: goldenprompts.ExpectRegex, Value: `(?i)\bjoin\b`},
+				{Type: goldenprompts.ExpectRegex, Value: `(?i)\bsum\s*\(\s*(o\.|orders\.)?total\s*\)`},
+				{Type: goldenprompts.ExpectRegex, Value: `(?i)group\s+by`},
+				{Type: goldenprompts.ExpectRegex, Value: `(?i)order\s+by[^;]*\bdesc\b`},
+				{Type: goldenprompts.ExpectContains, Value: "2025"},
+			},
+		},
+	}
+}
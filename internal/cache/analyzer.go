@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/provider"
 )
 
 // Analyzer analyzes request patterns to identify caching opportunities
@@ -302,7 +303,10 @@ func (a *Analyzer) generateRecommendations(opportunities []*CacheOpportunity) []
 
 // Helper functions
 
+// hashRequest hashes the canonical form of the request so identical prompts
+// logged in different provider dialects are counted as duplicates
 func (a *Analyzer) hashRequest(providerID, modelName, requestBody string) string {
+	requestBody = provider.CanonicalizeRequestBody(requestBody)
 	hasher := sha256.New()
 	hasher.Write([]byte(providerID))
 	hasher.Write([]byte(":"))
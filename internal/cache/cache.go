@@ -5,9 +5,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
 )
 
 // Entry represents a cached response
@@ -21,6 +24,7 @@ type Entry struct {
 	ProviderID  string                 `json:"provider_id"`
 	ModelName   string                 `json:"model_name"`
 	TokensSaved int64                  `json:"tokens_saved"` // Cumulative tokens saved from cache hits
+	Size        int64                  `json:"size"`         // Stored size in bytes, after any compression
 }
 
 // Config defines cache configuration
@@ -28,8 +32,17 @@ type Config struct {
 	Enabled       bool          `json:"enabled"`
 	DefaultTTL    time.Duration `json:"default_ttl"`    // Default time-to-live for cache entries
 	MaxSize       int           `json:"max_size"`       // Maximum number of entries
-	MaxMemoryMB   int           `json:"max_memory_mb"`  // Maximum memory usage (approximate)
+	MaxMemoryMB   int           `json:"max_memory_mb"`  // Budget for the stored size of all entries; 0 is unlimited
 	CleanupPeriod time.Duration `json:"cleanup_period"` // How often to run cleanup
+
+	// Compression of serialized (Redis) entries: CompressionNone,
+	// CompressionGzip or CompressionZstd. Entries smaller than
+	// CompressMinBytes are stored as plain JSON.
+	Compression      string `json:"compression,omitempty"`
+	CompressMinBytes int    `json:"compress_min_bytes,omitempty"`
+
+	// Keys controls the canonicalization of requests into keys by Key
+	Keys KeyConfig `json:"keys"`
 }
 
 // DefaultConfig returns sensible defaults for caching
@@ -63,6 +76,8 @@ type Cache struct {
 	entries map[string]*Entry
 	mu      sync.RWMutex
 	stats   *Stats
+	bytes   int64 // Stored size of all entries
+	pricer  analytics.Pricer
 }
 
 // Stats tracks cache performance
@@ -75,6 +90,37 @@ type Stats struct {
 	TokensSaved       int64   `json:"tokens_saved"`
 	CostSavedUSD      float64 `json:"cost_saved_usd"`
 	AvgLatencySavedMs int64   `json:"avg_latency_saved_ms"`
+
+	// Size accounting
+	TotalBytes            int64 `json:"total_bytes"`             // Stored size of all entries
+	AvgEntryBytes         int64 `json:"avg_entry_bytes"`         // Mean stored size of an entry
+	LargestEntryBytes     int64 `json:"largest_entry_bytes"`     // Stored size of the largest entry
+	MaxBytes              int64 `json:"max_bytes,omitempty"`     // Memory budget; 0 is unlimited
+	CompressionSavedBytes int64 `json:"compression_saved_bytes"` // Bytes saved by compressing entries as they were stored
+
+	// Invalidation
+	Invalidations      int64   `json:"invalidations"`
+	InvalidatedEntries int64   `json:"invalidated_entries"`
+	LastInvalidationMs float64 `json:"last_invalidation_ms"`
+	AvgInvalidationMs  float64 `json:"avg_invalidation_ms"`
+	invalidationTime   time.Duration
+}
+
+// recordInvalidation adds an invalidation's latency and removed entries
+func (s *Stats) recordInvalidation(d time.Duration, removed int) {
+	s.Invalidations++
+	s.InvalidatedEntries += int64(removed)
+	s.invalidationTime += d
+	s.LastInvalidationMs = float64(d.Microseconds()) / 1000
+	s.AvgInvalidationMs = float64(s.invalidationTime.Microseconds()) / 1000 / float64(s.Invalidations)
+}
+
+// ErrEntryTooLarge is returned when an entry alone exceeds the memory budget
+var ErrEntryTooLarge = errors.New("cache entry exceeds the memory budget")
+
+// maxBytes returns the memory budget in bytes, or 0 when unlimited
+func (c *Config) maxBytes() int64 {
+	return int64(c.MaxMemoryMB) * 1024 * 1024
 }
 
 // New creates a new in-memory cache instance
@@ -134,7 +180,15 @@ func (c *Cache) Get(ctx context.Context, key string) (*Entry, bool) {
 
 	// Use backend if available
 	if c.backend != nil {
-		return c.backend.Get(ctx, key)
+		entry, ok := c.backend.Get(ctx, key)
+		if ok {
+			if saved := c.costSaved(entry); saved > 0 {
+				c.mu.Lock()
+				c.stats.CostSavedUSD += saved
+				c.mu.Unlock()
+			}
+		}
+		return entry, ok
 	}
 
 	// In-memory implementation
@@ -151,7 +205,7 @@ func (c *Cache) Get(ctx context.Context, key string) (*Entry, bool) {
 	if time.Now().After(entry.ExpiresAt) {
 		// Expired - remove it
 		c.mu.Lock()
-		delete(c.entries, key)
+		c.remove(key)
 		c.mu.Unlock()
 		c.updateStats(false, 0, 0)
 		return nil, false
@@ -162,10 +216,36 @@ func (c *Cache) Get(ctx context.Context, key string) (*Entry, bool) {
 	entry.Hits++
 	c.mu.Unlock()
 
-	c.updateStats(true, entry.TokensSaved, 0)
+	c.updateStats(true, entry.TokensSaved, c.costSaved(entry))
 	return entry, true
 }
 
+// SetPricer makes cache hits count what the request would have cost, at
+// the pricing catalog's price for the entry's model, into CostSavedUSD
+func (c *Cache) SetPricer(p analytics.Pricer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pricer = p
+}
+
+// costSaved prices the request a hit on entry answered. Entries cached
+// without a prompt and completion split are priced as all prompt.
+func (c *Cache) costSaved(entry *Entry) float64 {
+	c.mu.RLock()
+	pricer := c.pricer
+	c.mu.RUnlock()
+	if pricer == nil || entry == nil {
+		return 0
+	}
+	prompt := getInt64FromMap(entry.Metadata, "prompt_tokens")
+	completion := getInt64FromMap(entry.Metadata, "completion_tokens")
+	if prompt+completion == 0 {
+		prompt = entry.TokensSaved
+	}
+	cost, _ := pricer.Cost(entry.ProviderID, entry.ModelName, prompt, completion, 0)
+	return cost
+}
+
 // Set stores a response in the cache
 func (c *Cache) Set(ctx context.Context, key string, response interface{}, ttl time.Duration, metadata map[string]interface{}) error {
 	if !c.config.Enabled {
@@ -177,6 +257,11 @@ func (c *Cache) Set(ctx context.Context, key string, response interface{}, ttl t
 		ttl = c.config.DefaultTTL
 	}
 
+	// Use backend if available
+	if c.backend != nil {
+		return c.backend.Set(ctx, key, response, ttl, metadata)
+	}
+
 	entry := &Entry{
 		Key:         key,
 		Response:    response,
@@ -189,15 +274,32 @@ func (c *Cache) Set(ctx context.Context, key string, response interface{}, ttl t
 		TokensSaved: getInt64FromMap(metadata, "total_tokens"),
 	}
 
+	// In memory the entry is kept as is; its size is that of its JSON
+	enc, err := encodeEntry(entry, CompressionNone, 0)
+	if err != nil {
+		return err
+	}
+	entry.Size = int64(len(enc.data))
+	maxBytes := c.config.maxBytes()
+	if maxBytes > 0 && entry.Size > maxBytes {
+		return ErrEntryTooLarge
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	c.remove(key)
+
 	// Check if we need to evict entries
 	if len(c.entries) >= c.config.MaxSize {
 		c.evictOldest()
 	}
+	for maxBytes > 0 && c.bytes+entry.Size > maxBytes && len(c.entries) > 0 {
+		c.evictForSize()
+	}
 
 	c.entries[key] = entry
+	c.bytes += entry.Size
 	return nil
 }
 
@@ -206,9 +308,13 @@ func (c *Cache) Delete(ctx context.Context, key string) {
 	if !c.config.Enabled {
 		return
 	}
+	if c.backend != nil {
+		c.backend.Delete(ctx, key)
+		return
+	}
 
 	c.mu.Lock()
-	delete(c.entries, key)
+	c.remove(key)
 	c.mu.Unlock()
 }
 
@@ -217,9 +323,14 @@ func (c *Cache) Clear(ctx context.Context) {
 	if !c.config.Enabled {
 		return
 	}
+	if c.backend != nil {
+		c.backend.Clear(ctx)
+		return
+	}
 
 	c.mu.Lock()
 	c.entries = make(map[string]*Entry)
+	c.bytes = 0
 	c.mu.Unlock()
 }
 
@@ -228,14 +339,19 @@ func (c *Cache) InvalidateByProvider(ctx context.Context, providerID string) int
 	if !c.config.Enabled {
 		return 0
 	}
+	if c.backend != nil {
+		return c.backend.InvalidateByProvider(ctx, providerID)
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	removed := 0
+	defer func(start time.Time) { c.stats.recordInvalidation(time.Since(start), removed) }(time.Now())
+
 	for key, entry := range c.entries {
 		if entry.ProviderID == providerID {
-			delete(c.entries, key)
+			c.remove(key)
 			removed++
 		}
 	}
@@ -248,14 +364,19 @@ func (c *Cache) InvalidateByModel(ctx context.Context, modelName string) int {
 	if !c.config.Enabled {
 		return 0
 	}
+	if c.backend != nil {
+		return c.backend.InvalidateByModel(ctx, modelName)
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	removed := 0
+	defer func(start time.Time) { c.stats.recordInvalidation(time.Since(start), removed) }(time.Now())
+
 	for key, entry := range c.entries {
 		if entry.ModelName == modelName {
-			delete(c.entries, key)
+			c.remove(key)
 			removed++
 		}
 	}
@@ -268,16 +389,21 @@ func (c *Cache) InvalidateByAge(ctx context.Context, maxAge time.Duration) int {
 	if !c.config.Enabled {
 		return 0
 	}
+	if c.backend != nil {
+		return c.backend.InvalidateByAge(ctx, maxAge)
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	threshold := time.Now().Add(-maxAge)
 	removed := 0
+	defer func(start time.Time) { c.stats.recordInvalidation(time.Since(start), removed) }(time.Now())
+
+	threshold := time.Now().Add(-maxAge)
 
 	for key, entry := range c.entries {
 		if entry.CachedAt.Before(threshold) {
-			delete(c.entries, key)
+			c.remove(key)
 			removed++
 		}
 	}
@@ -290,15 +416,20 @@ func (c *Cache) InvalidateByPattern(ctx context.Context, pattern string) int {
 	if !c.config.Enabled {
 		return 0
 	}
+	if c.backend != nil {
+		return c.backend.InvalidateByPattern(ctx, pattern)
+	}
 
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	removed := 0
+	defer func(start time.Time) { c.stats.recordInvalidation(time.Since(start), removed) }(time.Now())
+
 	for key := range c.entries {
 		// Simple prefix match for now
 		if len(key) >= len(pattern) && key[:len(pattern)] == pattern {
-			delete(c.entries, key)
+			c.remove(key)
 			removed++
 		}
 	}
@@ -308,11 +439,23 @@ func (c *Cache) InvalidateByPattern(ctx context.Context, pattern string) int {
 
 // GetStats returns current cache statistics
 func (c *Cache) GetStats(ctx context.Context) *Stats {
+	if c.backend != nil {
+		return c.backend.GetStats(ctx)
+	}
+
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	stats := *c.stats
 	stats.TotalEntries = int64(len(c.entries))
+	stats.TotalBytes = c.bytes
+	stats.MaxBytes = c.config.maxBytes()
+	if len(c.entries) > 0 {
+		stats.AvgEntryBytes = c.bytes / int64(len(c.entries))
+	}
+	for _, entry := range c.entries {
+		stats.LargestEntryBytes = max(stats.LargestEntryBytes, entry.Size)
+	}
 
 	// Calculate hit rate
 	total := stats.Hits + stats.Misses
@@ -342,7 +485,7 @@ func (c *Cache) cleanup() {
 
 	for key, entry := range c.entries {
 		if now.After(entry.ExpiresAt) {
-			delete(c.entries, key)
+			c.remove(key)
 		}
 	}
 }
@@ -363,19 +506,45 @@ func (c *Cache) evictOldest() {
 	}
 
 	if oldestKey != "" {
-		delete(c.entries, oldestKey)
+		c.remove(oldestKey)
 		c.stats.Evictions++
 	}
 }
 
+// evictForSize removes the entry that earns the fewest hits per stored
+// byte. Ties go to the oldest entry.
+func (c *Cache) evictForSize() {
+	var victim *Entry
+	for _, entry := range c.entries {
+		if victim == nil || evictBefore(entry.Hits, entry.Size, victim.Hits, victim.Size) ||
+			(!evictBefore(victim.Hits, victim.Size, entry.Hits, entry.Size) && entry.CachedAt.Before(victim.CachedAt)) {
+			victim = entry
+		}
+	}
+
+	if victim != nil {
+		c.remove(victim.Key)
+		c.stats.Evictions++
+	}
+}
+
+// remove deletes an entry and its size from the total; the caller holds mu
+func (c *Cache) remove(key string) {
+	if entry, ok := c.entries[key]; ok {
+		c.bytes -= entry.Size
+		delete(c.entries, key)
+	}
+}
+
 // updateStats updates cache statistics
-func (c *Cache) updateStats(hit bool, tokensSaved, costSavedUSD int64) {
+func (c *Cache) updateStats(hit bool, tokensSaved int64, costSavedUSD float64) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	if hit {
 		c.stats.Hits++
 		c.stats.TokensSaved += tokensSaved
+		c.stats.CostSavedUSD += costSavedUSD
 	} else {
 		c.stats.Misses++
 	}
@@ -0,0 +1,138 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// DefaultStripFields are the top-level request fields that identify a
+// request or its caller but do not change the completion
+var DefaultStripFields = []string{"request_id", "user", "user_id", "tags", "metadata"}
+
+// KeyConfig controls how requests are canonicalized into cache keys, so
+// logically identical requests share an entry
+type KeyConfig struct {
+	Canonicalize bool `json:"canonicalize"`
+	// StripFields are top-level fields ignored when computing keys;
+	// empty uses DefaultStripFields
+	StripFields []string `json:"strip_fields,omitempty"`
+	// IgnoreTemperatureBelow drops temperatures under this value from keys,
+	// treating near-deterministic sampling as deterministic; 0 keeps them
+	IgnoreTemperatureBelow float64 `json:"ignore_temperature_below,omitempty"`
+}
+
+// CanonicalizeRequest returns the canonical JSON of a request: requests of
+// other provider dialects are rewritten in the OpenAI shape, non-semantic
+// fields are stripped, message roles and text are normalized, and object
+// keys are sorted. request may be a value, raw JSON bytes or a JSON string.
+func CanonicalizeRequest(request interface{}, cfg KeyConfig) ([]byte, error) {
+	raw, err := requestJSON(request)
+	if err != nil {
+		return nil, err
+	}
+	raw = []byte(provider.CanonicalizeRequestBody(string(raw)))
+
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	if m, ok := v.(map[string]interface{}); ok {
+		strip := cfg.StripFields
+		if len(strip) == 0 {
+			strip = DefaultStripFields
+		}
+		for _, field := range strip {
+			delete(m, field)
+		}
+		if t, ok := m["temperature"].(float64); ok && t < cfg.IgnoreTemperatureBelow {
+			delete(m, "temperature")
+		}
+		if messages, ok := m["messages"].([]interface{}); ok {
+			for _, msg := range messages {
+				if msg, ok := msg.(map[string]interface{}); ok {
+					normalizeMessage(msg)
+				}
+			}
+		}
+		if prompt, ok := m["prompt"].(string); ok {
+			m["prompt"] = normalizeText(prompt)
+		}
+	}
+
+	// Marshaling sorts object keys
+	return json.Marshal(v)
+}
+
+// Key returns the cache key of a request, canonicalized when the cache is
+// configured to
+func (c *Cache) Key(providerID, model string, request interface{}) (string, error) {
+	if !c.config.Keys.Canonicalize {
+		return GenerateKey(providerID, model, request)
+	}
+	body, err := CanonicalizeRequest(request, c.config.Keys)
+	if err != nil {
+		return "", err
+	}
+	return GenerateKey(providerID, model, json.RawMessage(body))
+}
+
+// requestJSON returns the JSON of a request
+func requestJSON(request interface{}) ([]byte, error) {
+	switch r := request.(type) {
+	case json.RawMessage:
+		return r, nil
+	case []byte:
+		if json.Valid(r) {
+			return r, nil
+		}
+		return json.Marshal(string(r))
+	case string:
+		if json.Valid([]byte(r)) {
+			return []byte(r), nil
+		}
+	}
+	data, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	return data, nil
+}
+
+// normalizeMessage lower-cases a message's role and normalizes its text.
+// Content made of a single text part becomes a plain string.
+func normalizeMessage(msg map[string]interface{}) {
+	if role, ok := msg["role"].(string); ok {
+		msg["role"] = strings.ToLower(strings.TrimSpace(role))
+	}
+	switch content := msg["content"].(type) {
+	case string:
+		msg["content"] = normalizeText(content)
+	case []interface{}:
+		for _, part := range content {
+			if part, ok := part.(map[string]interface{}); ok {
+				if text, ok := part["text"].(string); ok {
+					part["text"] = normalizeText(text)
+				}
+			}
+		}
+		if len(content) == 1 {
+			if part, ok := content[0].(map[string]interface{}); ok && part["type"] == "text" && len(part) == 2 {
+				msg["content"] = part["text"]
+			}
+		}
+	}
+}
+
+// normalizeText unifies line endings and drops trailing spaces on each
+// line and blank space around the text. Indentation is kept, since it can
+// matter (code, YAML).
+func normalizeText(s string) string {
+	lines := strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestCanonicalizeRequest_IdenticalRequestsMatch(t *testing.T) {
+	cfg := KeyConfig{Canonicalize: true, IgnoreTemperatureBelow: 0.2}
+	variants := []interface{}{
+		`{"model":"gpt-4","messages":[{"role":"user","content":"Fix the bug\n"}],"temperature":0.1}`,
+		`{"messages": [{"content": "  Fix the bug", "role": "User"}], "model": "gpt-4", "request_id": "r-1", "user": "alice"}`,
+		[]byte("{\"model\":\"gpt-4\",\"messages\":[{\"role\":\"user\",\"content\":[{\"type\":\"text\",\"text\":\"Fix the bug \\r\\n\"}]}]}"),
+		map[string]interface{}{
+			"model":    "gpt-4",
+			"messages": []map[string]string{{"role": "user", "content": "Fix the bug"}},
+			"metadata": map[string]string{"trace": "x"},
+		},
+	}
+
+	want, err := CanonicalizeRequest(variants[0], cfg)
+	if err != nil {
+		t.Fatalf("CanonicalizeRequest failed: %v", err)
+	}
+	if string(want) != `{"messages":[{"content":"Fix the bug","role":"user"}],"model":"gpt-4"}` {
+		t.Fatalf("unexpected canonical form %s", want)
+	}
+	for i, v := range variants[1:] {
+		got, err := CanonicalizeRequest(v, cfg)
+		if err != nil {
+			t.Fatalf("variant %d: %v", i+1, err)
+		}
+		if string(got) != string(want) {
+			t.Errorf("variant %d: got %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestCanonicalizeRequest_SemanticDifferencesMiss(t *testing.T) {
+	cfg := KeyConfig{Canonicalize: true, IgnoreTemperatureBelow: 0.2}
+	base := `{"model":"gpt-4","messages":[{"role":"user","content":"def f():\n    return 1"}]}`
+	different := []string{
+		`{"model":"gpt-4","messages":[{"role":"user","content":"def f():\nreturn 1"}]}`,                       // Indentation kept
+		`{"model":"gpt-4","messages":[{"role":"user","content":"def f():\n    return 1"}],"temperature":0.7}`, // Above the threshold
+		`{"model":"gpt-4","messages":[{"role":"system","content":"def f():\n    return 1"}]}`,
+	}
+	want, _ := CanonicalizeRequest(base, cfg)
+	for i, d := range different {
+		if got, _ := CanonicalizeRequest(d, cfg); string(got) == string(want) {
+			t.Errorf("request %d: expected a different key than the base", i)
+		}
+	}
+
+	// Custom strip fields replace the defaults
+	a, _ := CanonicalizeRequest(`{"prompt":"hi","user":"a","session":"1"}`, KeyConfig{StripFields: []string{"session"}})
+	b, _ := CanonicalizeRequest(`{"prompt":"hi","user":"a","session":"2"}`, KeyConfig{StripFields: []string{"session"}})
+	if string(a) != string(b) || string(a) != `{"prompt":"hi","user":"a"}` {
+		t.Errorf("unexpected canonical forms %s, %s", a, b)
+	}
+}
+
+func TestCacheKey_Canonicalization(t *testing.T) {
+	a := json.RawMessage(`{"b":1,"a":2,"user":"x"}`)
+	b := json.RawMessage(`{"a":2,"b":1}`)
+
+	plain := New(&Config{Enabled: true})
+	ka, _ := plain.Key("p", "m", a)
+	kb, _ := plain.Key("p", "m", b)
+	if ka == kb {
+		t.Error("expected keys to differ without canonicalization")
+	}
+
+	canon := New(&Config{Enabled: true, Keys: KeyConfig{Canonicalize: true}})
+	ka, _ = canon.Key("p", "m", a)
+	kb, _ = canon.Key("p", "m", b)
+	if ka != kb {
+		t.Error("expected canonicalized keys to match")
+	}
+	if kc, _ := canon.Key("p", "other", b); kc == kb {
+		t.Error("expected the model to stay part of the key")
+	}
+}
@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression algorithms for serialized entries
+const (
+	CompressionNone = ""
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// DefaultCompressMinBytes is the size below which entries are stored
+// uncompressed, since compressing them saves little
+const DefaultCompressMinBytes = 1024
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+	zstdOnce    sync.Once
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+	zstdErr     error
+)
+
+// encodedEntry is a serialized entry and the size it had before
+// compression
+type encodedEntry struct {
+	data []byte
+	raw  int
+}
+
+// encodeEntry serializes an entry, compressing it with the configured
+// algorithm when it is at least minBytes long and compression shrinks it
+func encodeEntry(entry *Entry, compression string, minBytes int) (*encodedEntry, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal entry: %w", err)
+	}
+	enc := &encodedEntry{data: data, raw: len(data)}
+	if compression == CompressionNone || len(data) < minBytes {
+		return enc, nil
+	}
+
+	var compressed []byte
+	switch compression {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to compress entry: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("failed to compress entry: %w", err)
+		}
+		compressed = buf.Bytes()
+	case CompressionZstd:
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		compressed = zstdEncoder.EncodeAll(data, nil)
+	default:
+		return nil, fmt.Errorf("unknown compression %q", compression)
+	}
+	if len(compressed) < len(data) {
+		enc.data = compressed
+	}
+	return enc, nil
+}
+
+// decodeEntry deserializes an entry written by encodeEntry. The algorithm
+// is detected from the payload, so entries written before compression was
+// enabled (or with another algorithm) still decode.
+func decodeEntry(data []byte) (*Entry, error) {
+	raw := data
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress entry: %w", err)
+		}
+		defer zr.Close()
+		if raw, err = io.ReadAll(zr); err != nil {
+			return nil, fmt.Errorf("failed to decompress entry: %w", err)
+		}
+	case bytes.HasPrefix(data, zstdMagic):
+		if err := initZstd(); err != nil {
+			return nil, err
+		}
+		var err error
+		if raw, err = zstdDecoder.DecodeAll(data, nil); err != nil {
+			return nil, fmt.Errorf("failed to decompress entry: %w", err)
+		}
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal entry: %w", err)
+	}
+	entry.Size = int64(len(data))
+	return &entry, nil
+}
+
+// initZstd creates the shared zstd encoder and decoder, which are safe for
+// concurrent EncodeAll and DecodeAll calls
+func initZstd() error {
+	zstdOnce.Do(func() {
+		if zstdEncoder, zstdErr = zstd.NewWriter(nil); zstdErr != nil {
+			return
+		}
+		zstdDecoder, zstdErr = zstd.NewReader(nil)
+	})
+	if zstdErr != nil {
+		return fmt.Errorf("failed to initialize zstd: %w", zstdErr)
+	}
+	return nil
+}
+
+// compressMinBytes returns the size below which entries are stored
+// uncompressed
+func (c *Config) compressMinBytes() int {
+	if c.CompressMinBytes > 0 {
+		return c.CompressMinBytes
+	}
+	return DefaultCompressMinBytes
+}
+
+// evictBefore reports whether an entry with hitsA hits and sizeA stored
+// bytes should be evicted before one with hitsB and sizeB: the one earning
+// fewer hits per byte goes first, so one large, rarely hit completion goes
+// before many small, hot ones
+func evictBefore(hitsA, sizeA, hitsB, sizeB int64) bool {
+	// Compare (hits+1)/size without dividing
+	return (hitsA+1)*max(sizeB, 1) < (hitsB+1)*max(sizeA, 1)
+}
+
+// validCompression reports whether c names a supported algorithm
+func validCompression(c string) bool {
+	return c == CompressionNone || c == CompressionGzip || c == CompressionZstd
+}
@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncodeEntry_Compression(t *testing.T) {
+	entry := &Entry{Key: "k", Response: strings.Repeat("a long completion ", 200), ProviderID: "openai"}
+
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		enc, err := encodeEntry(entry, compression, DefaultCompressMinBytes)
+		if err != nil {
+			t.Fatalf("%q: encodeEntry failed: %v", compression, err)
+		}
+		if compression != CompressionNone && len(enc.data) >= enc.raw {
+			t.Errorf("%q: expected %d bytes to shrink, got %d", compression, enc.raw, len(enc.data))
+		}
+		decoded, err := decodeEntry(enc.data)
+		if err != nil {
+			t.Fatalf("%q: decodeEntry failed: %v", compression, err)
+		}
+		if decoded.Response != entry.Response || decoded.ProviderID != "openai" || decoded.Size != int64(len(enc.data)) {
+			t.Errorf("%q: unexpected entry %+v", compression, decoded)
+		}
+	}
+
+	small := &Entry{Key: "k", Response: "tiny"}
+	if enc, _ := encodeEntry(small, CompressionZstd, DefaultCompressMinBytes); len(enc.data) != enc.raw {
+		t.Error("expected entries under the threshold to be stored uncompressed")
+	}
+	if _, err := encodeEntry(entry, "lz4", 0); err == nil {
+		t.Error("expected an unknown compression to fail")
+	}
+	if _, err := decodeEntry([]byte("not an entry")); err == nil {
+		t.Error("expected garbage to fail to decode")
+	}
+}
+
+func TestCacheMemoryBudget(t *testing.T) {
+	c := New(&Config{Enabled: true, DefaultTTL: time.Hour, MaxSize: 100, MaxMemoryMB: 1})
+	ctx := context.Background()
+	big := strings.Repeat("x", 400*1024)
+
+	if err := c.Set(ctx, "hot", "small", 0, nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	c.Get(ctx, "hot")
+	if err := c.Set(ctx, "big-1", big, 0, nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := c.Set(ctx, "big-2", big, 0, nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// A third large entry overflows the budget: the cold large entry goes,
+	// not the small, hot one
+	if err := c.Set(ctx, "big-3", big, 0, nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if _, ok := c.Get(ctx, "hot"); !ok {
+		t.Error("expected the small, hot entry to survive")
+	}
+	if _, ok := c.Get(ctx, "big-3"); !ok {
+		t.Error("expected the new entry to be stored")
+	}
+
+	stats := c.GetStats(ctx)
+	if stats.TotalEntries != 3 || stats.Evictions != 1 || stats.TotalBytes > stats.MaxBytes {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.LargestEntryBytes < int64(len(big)) || stats.AvgEntryBytes != stats.TotalBytes/3 {
+		t.Errorf("unexpected entry sizes %+v", stats)
+	}
+
+	if err := c.Set(ctx, "huge", strings.Repeat("x", 2*1024*1024), 0, nil); !errors.Is(err, ErrEntryTooLarge) {
+		t.Errorf("expected an entry over the budget to be rejected, got %v", err)
+	}
+	c.Delete(ctx, "big-3")
+	c.Clear(ctx)
+	if stats := c.GetStats(ctx); stats.TotalBytes != 0 {
+		t.Errorf("expected no bytes after clearing, got %d", stats.TotalBytes)
+	}
+}
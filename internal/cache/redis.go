@@ -2,13 +2,22 @@ package cache
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
+// Hashes of the stored size and hit count of each entry, by cache key,
+// which back the memory budget without reading every entry
+const (
+	redisSizesKey = "cachemeta:sizes"
+	redisHitsKey  = "cachemeta:hits"
+)
+
 // RedisCache implements Cache using Redis as the backend
 type RedisCache struct {
 	client *redis.Client
@@ -21,6 +30,9 @@ func NewRedisCache(redisURL string, config *Config) (*RedisCache, error) {
 	if config == nil {
 		config = DefaultConfig()
 	}
+	if !validCompression(config.Compression) {
+		return nil, fmt.Errorf("unknown cache compression %q", config.Compression)
+	}
 
 	// Parse Redis URL and create client
 	opt, err := redis.ParseURL(redisURL)
@@ -38,11 +50,13 @@ func NewRedisCache(redisURL string, config *Config) (*RedisCache, error) {
 		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
 	}
 
-	return &RedisCache{
+	rc := &RedisCache{
 		client: client,
 		config: config,
 		stats:  &Stats{},
-	}, nil
+	}
+	rc.ensureIndexed(context.Background())
+	return rc, nil
 }
 
 // Get retrieves a cached response from Redis
@@ -65,10 +79,10 @@ func (rc *RedisCache) Get(ctx context.Context, key string) (*Entry, bool) {
 	}
 
 	// Deserialize entry
-	var entry Entry
-	if err := json.Unmarshal([]byte(val), &entry); err != nil {
+	entry, err := decodeEntry([]byte(val))
+	if err != nil {
 		// Corrupted entry - delete and treat as miss
-		rc.client.Del(ctx, "cache:"+key)
+		rc.del(ctx, key)
 		rc.stats.Misses++
 		return nil, false
 	}
@@ -81,14 +95,15 @@ func (rc *RedisCache) Get(ctx context.Context, key string) (*Entry, bool) {
 	entry.Hits++
 
 	// Update entry in Redis with new hit count
-	if data, err := json.Marshal(entry); err == nil {
+	if enc, err := rc.encode(entry); err == nil {
 		ttl := time.Until(entry.ExpiresAt)
 		if ttl > 0 {
-			rc.client.Set(ctx, "cache:"+key, data, ttl)
+			rc.client.Set(ctx, "cache:"+key, enc.data, ttl)
+			rc.client.HIncrBy(ctx, redisHitsKey, key, 1)
 		}
 	}
 
-	return &entry, true
+	return entry, true
 }
 
 // Set stores a response in Redis
@@ -114,13 +129,90 @@ func (rc *RedisCache) Set(ctx context.Context, key string, response interface{},
 	}
 
 	// Serialize entry
-	data, err := json.Marshal(entry)
+	enc, err := rc.encode(entry)
 	if err != nil {
-		return fmt.Errorf("failed to marshal entry: %w", err)
+		return err
+	}
+	size := int64(len(enc.data))
+	if maxBytes := rc.config.maxBytes(); maxBytes > 0 && size > maxBytes {
+		return ErrEntryTooLarge
 	}
 
 	// Store in Redis with TTL
-	return rc.client.Set(ctx, "cache:"+key, data, ttl).Err()
+	if err := rc.client.Set(ctx, "cache:"+key, enc.data, ttl).Err(); err != nil {
+		return err
+	}
+	rc.stats.CompressionSavedBytes += int64(enc.raw) - size
+	rc.client.HSet(ctx, redisSizesKey, key, size)
+	rc.client.HDel(ctx, redisHitsKey, key)
+	rc.index(ctx, entry)
+	rc.pruneExpired(ctx)
+	rc.enforceBudget(ctx, key)
+	return nil
+}
+
+// encode serializes an entry with the configured compression
+func (rc *RedisCache) encode(entry *Entry) (*encodedEntry, error) {
+	return encodeEntry(entry, rc.config.Compression, rc.config.compressMinBytes())
+}
+
+// del removes an entry, its size accounting and its index members
+func (rc *RedisCache) del(ctx context.Context, key string) {
+	rc.invalidateKeys(ctx, []string{key})
+}
+
+// enforceBudget evicts entries, fewest hits per stored byte first, until
+// the stored size of all entries fits the memory budget. The entry just
+// stored (keep) is never evicted.
+func (rc *RedisCache) enforceBudget(ctx context.Context, keep string) {
+	maxBytes := rc.config.maxBytes()
+	if maxBytes <= 0 {
+		return
+	}
+	sizes, err := rc.client.HGetAll(ctx, redisSizesKey).Result()
+	if err != nil {
+		return
+	}
+	total := int64(0)
+	for _, v := range sizes {
+		size, _ := strconv.ParseInt(v, 10, 64)
+		total += size
+	}
+	if total <= maxBytes {
+		return
+	}
+	hits, _ := rc.client.HGetAll(ctx, redisHitsKey).Result()
+
+	type sized struct {
+		key        string
+		size, hits int64
+	}
+	var candidates []sized
+	for key, v := range sizes {
+		size, _ := strconv.ParseInt(v, 10, 64)
+		// Entries that expired leave their size behind
+		if n, err := rc.client.Exists(ctx, "cache:"+key).Result(); err == nil && n == 0 {
+			rc.del(ctx, key)
+			total -= size
+			continue
+		}
+		if key != keep {
+			h, _ := strconv.ParseInt(hits[key], 10, 64)
+			candidates = append(candidates, sized{key: key, size: size, hits: h})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return evictBefore(candidates[i].hits, candidates[i].size, candidates[j].hits, candidates[j].size)
+	})
+
+	for _, c := range candidates {
+		if total <= maxBytes {
+			break
+		}
+		rc.del(ctx, c.key)
+		total -= c.size
+		rc.stats.Evictions++
+	}
 }
 
 // Delete removes an entry from Redis
@@ -129,7 +221,7 @@ func (rc *RedisCache) Delete(ctx context.Context, key string) {
 		return
 	}
 
-	rc.client.Del(ctx, "cache:"+key)
+	rc.del(ctx, key)
 }
 
 // Clear removes all cache entries from Redis
@@ -143,19 +235,29 @@ func (rc *RedisCache) Clear(ctx context.Context) {
 	for iter.Next(ctx) {
 		rc.client.Del(ctx, iter.Val())
 	}
+	rc.client.Del(ctx, redisSizesKey, redisHitsKey)
+	rc.clearIndexes(ctx)
 }
 
 // GetStats returns cache statistics
 func (rc *RedisCache) GetStats(ctx context.Context) *Stats {
 	stats := *rc.stats
 
-	// Get count of cache entries from Redis
+	// Get count and stored size of cache entries from Redis
+	sizes, _ := rc.client.HGetAll(ctx, redisSizesKey).Result()
 	count := int64(0)
 	iter := rc.client.Scan(ctx, 0, "cache:*", 0).Iterator()
 	for iter.Next(ctx) {
 		count++
+		size, _ := strconv.ParseInt(sizes[strings.TrimPrefix(iter.Val(), "cache:")], 10, 64)
+		stats.TotalBytes += size
+		stats.LargestEntryBytes = max(stats.LargestEntryBytes, size)
 	}
 	stats.TotalEntries = count
+	stats.MaxBytes = rc.config.maxBytes()
+	if count > 0 {
+		stats.AvgEntryBytes = stats.TotalBytes / count
+	}
 
 	// Calculate hit rate
 	total := stats.Hits + stats.Misses
@@ -172,7 +274,10 @@ func (rc *RedisCache) InvalidateByProvider(ctx context.Context, providerID strin
 		return 0
 	}
 
-	return rc.invalidateByMetadata(ctx, "provider_id", providerID)
+	start := time.Now()
+	removed := rc.invalidateSet(ctx, redisProviderIndex+providerID)
+	rc.stats.recordInvalidation(time.Since(start), removed)
+	return removed
 }
 
 // InvalidateByModel removes all cache entries for a specific model
@@ -181,7 +286,10 @@ func (rc *RedisCache) InvalidateByModel(ctx context.Context, modelName string) i
 		return 0
 	}
 
-	return rc.invalidateByMetadata(ctx, "model_name", modelName)
+	start := time.Now()
+	removed := rc.invalidateSet(ctx, redisModelIndex+modelName)
+	rc.stats.recordInvalidation(time.Since(start), removed)
+	return removed
 }
 
 // InvalidateByAge removes entries older than the specified duration
@@ -190,28 +298,18 @@ func (rc *RedisCache) InvalidateByAge(ctx context.Context, maxAge time.Duration)
 		return 0
 	}
 
-	threshold := time.Now().Add(-maxAge)
-	removed := 0
-
-	iter := rc.client.Scan(ctx, 0, "cache:*", 0).Iterator()
-	for iter.Next(ctx) {
-		key := iter.Val()
-		val, err := rc.client.Get(ctx, key).Result()
-		if err != nil {
-			continue
-		}
-
-		var entry Entry
-		if err := json.Unmarshal([]byte(val), &entry); err != nil {
-			continue
-		}
-
-		if entry.CachedAt.Before(threshold) {
-			rc.client.Del(ctx, key)
-			removed++
-		}
+	start := time.Now()
+	threshold := start.Add(-maxAge).UnixMilli()
+	keys, err := rc.client.ZRangeByScore(ctx, redisCreatedIndex, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: "(" + strconv.FormatInt(threshold, 10),
+	}).Result()
+	if err != nil {
+		return 0
 	}
 
+	removed := rc.invalidateKeys(ctx, keys)
+	rc.stats.recordInvalidation(time.Since(start), removed)
 	return removed
 }
 
@@ -221,48 +319,15 @@ func (rc *RedisCache) InvalidateByPattern(ctx context.Context, pattern string) i
 		return 0
 	}
 
-	removed := 0
+	start := time.Now()
+	var keys []string
 	iter := rc.client.Scan(ctx, 0, "cache:"+pattern+"*", 0).Iterator()
 	for iter.Next(ctx) {
-		rc.client.Del(ctx, iter.Val())
-		removed++
-	}
-
-	return removed
-}
-
-// invalidateByMetadata is a helper to invalidate by metadata field
-func (rc *RedisCache) invalidateByMetadata(ctx context.Context, field, value string) int {
-	removed := 0
-
-	iter := rc.client.Scan(ctx, 0, "cache:*", 0).Iterator()
-	for iter.Next(ctx) {
-		key := iter.Val()
-		val, err := rc.client.Get(ctx, key).Result()
-		if err != nil {
-			continue
-		}
-
-		var entry Entry
-		if err := json.Unmarshal([]byte(val), &entry); err != nil {
-			continue
-		}
-
-		// Check metadata field
-		shouldInvalidate := false
-		switch field {
-		case "provider_id":
-			shouldInvalidate = entry.ProviderID == value
-		case "model_name":
-			shouldInvalidate = entry.ModelName == value
-		}
-
-		if shouldInvalidate {
-			rc.client.Del(ctx, key)
-			removed++
-		}
+		keys = append(keys, strings.TrimPrefix(iter.Val(), "cache:"))
 	}
 
+	removed := rc.invalidateKeys(ctx, keys)
+	rc.stats.recordInvalidation(time.Since(start), removed)
 	return removed
 }
 
@@ -0,0 +1,182 @@
+package cache
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Secondary indexes of Redis entries, so invalidation is a set lookup and
+// batched DELs rather than a SCAN that reads every entry
+const (
+	redisProviderIndex = "cacheidx:provider:" // Set of keys per provider
+	redisModelIndex    = "cacheidx:model:"    // Set of keys per model
+	redisCreatedIndex  = "cacheidx:created"   // Keys scored by creation time in Unix milliseconds
+	redisExpiresIndex  = "cacheidx:expires"   // Keys scored by expiry time in Unix milliseconds
+	redisMembersKey    = "cacheidx:members"   // Hash of key to the provider and model sets listing it
+	redisIndexVersion  = "cacheidx:version"   // Set once the indexes cover every entry
+	redisDelBatch      = 500
+)
+
+// indexSets returns the provider and model sets an entry belongs to
+func indexSets(providerID, modelName string) []string {
+	var sets []string
+	if providerID != "" {
+		sets = append(sets, redisProviderIndex+providerID)
+	}
+	if modelName != "" {
+		sets = append(sets, redisModelIndex+modelName)
+	}
+	return sets
+}
+
+// index adds an entry to the secondary indexes. An entry stored again is
+// first taken out of the sets it was listed in, so changing its provider
+// or model does not leave it behind in the old ones.
+func (rc *RedisCache) index(ctx context.Context, entry *Entry) {
+	prev, err := rc.client.HGet(ctx, redisMembersKey, entry.Key).Result()
+	if err != nil && err != redis.Nil {
+		return
+	}
+	sets := indexSets(entry.ProviderID, entry.ModelName)
+
+	pipe := rc.client.TxPipeline()
+	for _, set := range strings.Split(prev, "\n") {
+		if set != "" {
+			pipe.SRem(ctx, set, entry.Key)
+		}
+	}
+	for _, set := range sets {
+		pipe.SAdd(ctx, set, entry.Key)
+	}
+	pipe.HSet(ctx, redisMembersKey, entry.Key, strings.Join(sets, "\n"))
+	pipe.ZAdd(ctx, redisCreatedIndex, redis.Z{Score: float64(entry.CachedAt.UnixMilli()), Member: entry.Key})
+	pipe.ZAdd(ctx, redisExpiresIndex, redis.Z{Score: float64(entry.ExpiresAt.UnixMilli()), Member: entry.Key})
+	_, _ = pipe.Exec(ctx)
+}
+
+// pruneExpired drops up to a batch of entries that Redis expired from the
+// indexes and the size accounting, so they do not accumulate there
+func (rc *RedisCache) pruneExpired(ctx context.Context) {
+	keys, err := rc.client.ZRangeByScore(ctx, redisExpiresIndex, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + strconv.FormatInt(time.Now().UnixMilli(), 10),
+		Count: redisDelBatch,
+	}).Result()
+	if err != nil || len(keys) == 0 {
+		return
+	}
+	rc.invalidateKeys(ctx, keys)
+}
+
+// invalidateKeys deletes entries in batches, along with their size
+// accounting and index members, and returns how many existed
+func (rc *RedisCache) invalidateKeys(ctx context.Context, keys []string) int {
+	removed := 0
+	for start := 0; start < len(keys); start += redisDelBatch {
+		batch := keys[start:min(start+redisDelBatch, len(keys))]
+		redisKeys := make([]string, len(batch))
+		members := make([]interface{}, len(batch))
+		for i, key := range batch {
+			redisKeys[i] = "cache:" + key
+			members[i] = key
+		}
+		sets, err := rc.client.HMGet(ctx, redisMembersKey, batch...).Result()
+		if err != nil {
+			continue
+		}
+
+		pipe := rc.client.Pipeline()
+		del := pipe.Del(ctx, redisKeys...)
+		for i, v := range sets {
+			joined, _ := v.(string)
+			for _, set := range strings.Split(joined, "\n") {
+				if set != "" {
+					pipe.SRem(ctx, set, batch[i])
+				}
+			}
+		}
+		pipe.HDel(ctx, redisMembersKey, batch...)
+		pipe.HDel(ctx, redisSizesKey, batch...)
+		pipe.HDel(ctx, redisHitsKey, batch...)
+		pipe.ZRem(ctx, redisCreatedIndex, members...)
+		pipe.ZRem(ctx, redisExpiresIndex, members...)
+		if _, err := pipe.Exec(ctx); err != nil {
+			continue
+		}
+		removed += int(del.Val())
+	}
+	return removed
+}
+
+// invalidateSet deletes the entries a provider or model index lists, then
+// the index itself. Members of entries that already expired are dropped
+// without counting.
+func (rc *RedisCache) invalidateSet(ctx context.Context, index string) int {
+	keys, err := rc.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return 0
+	}
+	removed := rc.invalidateKeys(ctx, keys)
+	rc.client.Del(ctx, index)
+	return removed
+}
+
+// clearIndexes deletes every secondary index
+func (rc *RedisCache) clearIndexes(ctx context.Context) {
+	rc.client.Del(ctx, redisCreatedIndex, redisExpiresIndex, redisMembersKey)
+	for _, pattern := range []string{redisProviderIndex + "*", redisModelIndex + "*"} {
+		iter := rc.client.Scan(ctx, 0, pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			rc.client.Del(ctx, iter.Val())
+		}
+	}
+}
+
+// Reindex rebuilds the secondary indexes from the stored entries, dropping
+// whatever they listed before. It runs once when a cache written before the
+// indexes existed is opened.
+func (rc *RedisCache) Reindex(ctx context.Context) (int, error) {
+	rc.clearIndexes(ctx)
+	indexed := 0
+	iter := rc.client.Scan(ctx, 0, "cache:*", 0).Iterator()
+	for iter.Next(ctx) {
+		val, err := rc.client.Get(ctx, iter.Val()).Result()
+		if err != nil {
+			continue
+		}
+		entry, err := decodeEntry([]byte(val))
+		if err != nil {
+			continue
+		}
+		entry.Key = strings.TrimPrefix(iter.Val(), "cache:")
+		if time.Until(entry.ExpiresAt) > 0 {
+			rc.index(ctx, entry)
+			rc.client.HSet(ctx, redisSizesKey, entry.Key, entry.Size)
+			indexed++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return indexed, err
+	}
+	return indexed, rc.client.Set(ctx, redisIndexVersion, strconv.Itoa(1), 0).Err()
+}
+
+// ensureIndexed reindexes the cache unless its indexes are complete
+func (rc *RedisCache) ensureIndexed(ctx context.Context) {
+	if n, err := rc.client.Exists(ctx, redisIndexVersion).Result(); err != nil || n > 0 {
+		return
+	}
+	n, err := rc.Reindex(ctx)
+	if err != nil {
+		log.Printf("[Cache] Failed to index Redis cache entries: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[Cache] Indexed %d Redis cache entries", n)
+	}
+}
@@ -0,0 +1,152 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func newTestRedisCache(t *testing.T, config *Config) (*RedisCache, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rc, err := NewRedisCache("redis://"+mr.Addr(), config)
+	if err != nil {
+		t.Fatalf("NewRedisCache failed: %v", err)
+	}
+	t.Cleanup(func() { _ = rc.Close() })
+	return rc, mr
+}
+
+func TestRedisCache_IndexedInvalidation(t *testing.T) {
+	rc, mr := newTestRedisCache(t, &Config{Enabled: true, DefaultTTL: time.Hour, Compression: CompressionZstd})
+	ctx := context.Background()
+
+	set := func(key, providerID, model string) {
+		t.Helper()
+		if err := rc.Set(ctx, key, "response "+key, 0, map[string]interface{}{"provider_id": providerID, "model_name": model}); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	set("a", "openai", "gpt-4")
+	set("b", "openai", "gpt-4o")
+	set("c", "anthropic", "claude")
+	if !mr.Exists(redisProviderIndex+"openai") || !mr.Exists(redisModelIndex+"gpt-4") {
+		t.Fatal("expected entries to be indexed")
+	}
+
+	if removed := rc.InvalidateByProvider(ctx, "openai"); removed != 2 {
+		t.Errorf("expected 2 openai entries removed, got %d", removed)
+	}
+	if _, ok := rc.Get(ctx, "a"); ok {
+		t.Error("expected a to be invalidated")
+	}
+	// Invalidating a by provider also took it out of the model index
+	if mr.Exists(redisModelIndex + "gpt-4") {
+		t.Error("expected the gpt-4 index to be emptied")
+	}
+	if removed := rc.InvalidateByModel(ctx, "gpt-4"); removed != 0 {
+		t.Errorf("expected no gpt-4 entries left, got %d", removed)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	set("d", "anthropic", "claude")
+	if removed := rc.InvalidateByAge(ctx, 25*time.Millisecond); removed != 1 {
+		t.Errorf("expected only the old entry to be removed, got %d", removed)
+	}
+	if _, ok := rc.Get(ctx, "d"); !ok {
+		t.Error("expected the new entry to survive")
+	}
+
+	stats := rc.GetStats(ctx)
+	if stats.Invalidations != 3 || stats.InvalidatedEntries != 3 || stats.AvgInvalidationMs <= 0 || stats.TotalEntries != 1 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRedisCache_ReindexesExistingEntries(t *testing.T) {
+	mr := miniredis.RunT(t)
+	ctx := context.Background()
+
+	// An entry written before the indexes existed
+	enc, err := encodeEntry(&Entry{Key: "old", ProviderID: "openai", CachedAt: time.Now(), ExpiresAt: time.Now().Add(time.Hour)}, CompressionNone, 0)
+	if err != nil {
+		t.Fatalf("encodeEntry failed: %v", err)
+	}
+	if err := mr.Set("cache:old", string(enc.data)); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	rc, err := NewRedisCache("redis://"+mr.Addr(), &Config{Enabled: true, DefaultTTL: time.Hour})
+	if err != nil {
+		t.Fatalf("NewRedisCache failed: %v", err)
+	}
+	defer rc.Close()
+	if !mr.Exists(redisIndexVersion) {
+		t.Error("expected the indexes to be marked complete")
+	}
+	if removed := rc.InvalidateByProvider(ctx, "openai"); removed != 1 {
+		t.Errorf("expected the reindexed entry to be invalidated, got %d", removed)
+	}
+}
+
+func TestRedisCache_IndexesDoNotAccumulate(t *testing.T) {
+	rc, mr := newTestRedisCache(t, &Config{Enabled: true, DefaultTTL: time.Hour})
+	ctx := context.Background()
+
+	// Storing a key again under another provider moves it between sets
+	if err := rc.Set(ctx, "a", "one", 0, map[string]interface{}{"provider_id": "openai", "model_name": "gpt-4"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := rc.Set(ctx, "a", "two", 0, map[string]interface{}{"provider_id": "anthropic", "model_name": "claude"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if mr.Exists(redisProviderIndex+"openai") || mr.Exists(redisModelIndex+"gpt-4") {
+		t.Error("expected a to leave the openai and gpt-4 indexes")
+	}
+	if ok, _ := mr.SIsMember(redisProviderIndex+"anthropic", "a"); !ok {
+		t.Error("expected a in the anthropic index")
+	}
+
+	// Entries Redis expired are pruned from the indexes on a later Set
+	if err := rc.Set(ctx, "short", "x", time.Second, map[string]interface{}{"provider_id": "openai"}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	mr.FastForward(2 * time.Second)
+	time.Sleep(1100 * time.Millisecond) // Expiry scores are wall-clock times
+	if err := rc.Set(ctx, "b", "y", 0, nil); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if mr.Exists(redisProviderIndex + "openai") {
+		t.Error("expected the expired entry to leave the openai index")
+	}
+	if members, _ := mr.ZMembers(redisCreatedIndex); len(members) != 2 {
+		t.Errorf("expected only live entries in the creation index, got %v", members)
+	}
+	if mr.HGet(redisSizesKey, "short") != "" || mr.HGet(redisMembersKey, "short") != "" {
+		t.Error("expected the expired entry's accounting to be dropped")
+	}
+}
+
+func TestRedisCache_MemoryBudget(t *testing.T) {
+	rc, _ := newTestRedisCache(t, &Config{Enabled: true, DefaultTTL: time.Hour, MaxMemoryMB: 1})
+	ctx := context.Background()
+	big := make([]byte, 400*1024)
+	for i := range big {
+		big[i] = 'x'
+	}
+
+	for _, key := range []string{"big-1", "big-2", "big-3"} {
+		if err := rc.Set(ctx, key, string(big), 0, nil); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+	stats := rc.GetStats(ctx)
+	if stats.TotalEntries != 2 || stats.Evictions != 1 || stats.TotalBytes > stats.MaxBytes {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if _, ok := rc.Get(ctx, "big-3"); !ok {
+		t.Error("expected the newest entry to be kept")
+	}
+}
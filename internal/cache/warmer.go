@@ -0,0 +1,314 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// Warming strategies
+const (
+	WarmByFrequency = "frequency" // Most-repeated prompts first
+	WarmByCost      = "cost"      // Prompts that cost the most in total first
+)
+
+// WarmConfig configures cache warming from analytics history
+type WarmConfig struct {
+	TimeWindow     time.Duration // How far back to scan request logs
+	MaxEntries     int           // Maximum number of prompts to pre-populate
+	Strategy       string        // WarmByFrequency or WarmByCost
+	MinOccurrences int           // Minimum times a prompt must repeat to be warmed
+	TTL            time.Duration // TTL of warmed entries; 0 uses the cache default
+	Interval       time.Duration // How often to re-warm; 0 warms only at startup
+}
+
+// DefaultWarmConfig returns sensible defaults for cache warming
+func DefaultWarmConfig() *WarmConfig {
+	return &WarmConfig{
+		TimeWindow:     24 * time.Hour,
+		MaxEntries:     100,
+		Strategy:       WarmByFrequency,
+		MinOccurrences: 2,
+	}
+}
+
+// WarmReport is the outcome of a warming pass
+type WarmReport struct {
+	WarmedAt   time.Time `json:"warmed_at"`
+	Strategy   string    `json:"strategy"`
+	Scanned    int       `json:"scanned"`    // Request logs scanned
+	Candidates int       `json:"candidates"` // Repeated prompts with a logged response
+	Warmed     int       `json:"warmed"`     // Entries written to the cache
+	Errors     []string  `json:"errors,omitempty"`
+	Projected  *Stats    `json:"projected"` // Savings had the warmed entries served the window's repeats
+}
+
+// Warmer pre-populates the cache with the responses of the prompts that
+// repeat most (or cost most) in recent request logs
+type Warmer struct {
+	logStorage analytics.Storage
+	cache      *Cache
+	config     *WarmConfig
+
+	mu   sync.Mutex
+	last *WarmReport
+	stop chan struct{}
+}
+
+// warmCandidate is a repeated prompt and its most recent response
+type warmCandidate struct {
+	providerID  string
+	modelName   string
+	requestBody string
+	response    string
+	count       int
+	totalTokens int64
+	totalCost   float64
+	latencyMs   int64
+	lastSeen    time.Time
+}
+
+// NewWarmer creates a cache warmer reading logStorage
+func NewWarmer(logStorage analytics.Storage, c *Cache, config *WarmConfig) *Warmer {
+	defaults := DefaultWarmConfig()
+	if config == nil {
+		config = defaults
+	}
+	cfg := *config
+	if cfg.TimeWindow <= 0 {
+		cfg.TimeWindow = defaults.TimeWindow
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaults.MaxEntries
+	}
+	if cfg.Strategy == "" {
+		cfg.Strategy = defaults.Strategy
+	}
+	if cfg.MinOccurrences <= 0 {
+		cfg.MinOccurrences = defaults.MinOccurrences
+	}
+
+	return &Warmer{
+		logStorage: logStorage,
+		cache:      c,
+		config:     &cfg,
+	}
+}
+
+// Warm scans the configured window of request logs and caches the
+// responses of the top prompts. Requests logged without a response body
+// cannot be warmed.
+func (w *Warmer) Warm(ctx context.Context) (*WarmReport, error) {
+	if w.logStorage == nil || w.cache == nil {
+		return nil, fmt.Errorf("cache warming requires request logs and a cache")
+	}
+	if w.config.Strategy != WarmByFrequency && w.config.Strategy != WarmByCost {
+		return nil, fmt.Errorf("unknown warming strategy %q", w.config.Strategy)
+	}
+
+	now := time.Now()
+	logs, err := w.logStorage.GetLogs(ctx, &analytics.LogFilter{
+		StartTime: now.Add(-w.config.TimeWindow),
+		EndTime:   now,
+		Limit:     100000,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch logs: %w", err)
+	}
+
+	candidates := w.rank(w.candidates(logs))
+	report := &WarmReport{
+		WarmedAt:   now,
+		Strategy:   w.config.Strategy,
+		Scanned:    len(logs),
+		Candidates: len(candidates),
+		Projected:  &Stats{},
+	}
+	if len(candidates) > w.config.MaxEntries {
+		candidates = candidates[:w.config.MaxEntries]
+	}
+
+	var latencySaved int64
+	for _, cand := range candidates {
+		key, err := w.cache.Key(cand.providerID, cand.modelName, jsonOrString(cand.requestBody))
+		if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+			continue
+		}
+		avgTokens := cand.totalTokens / int64(cand.count)
+		if err := w.cache.Set(ctx, key, jsonOrString(cand.response), w.config.TTL, map[string]interface{}{
+			"provider_id":  cand.providerID,
+			"model_name":   cand.modelName,
+			"total_tokens": avgTokens,
+			"cost_usd":     cand.totalCost / float64(cand.count),
+			"warmed":       true,
+		}); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s/%s: %v", cand.providerID, cand.modelName, err))
+			continue
+		}
+		report.Warmed++
+
+		// Every occurrence after the first would have been a hit
+		repeats := int64(cand.count - 1)
+		p := report.Projected
+		p.Hits += repeats
+		p.Misses++
+		p.TokensSaved += avgTokens * repeats
+		p.CostSavedUSD += cand.totalCost / float64(cand.count) * float64(repeats)
+		latencySaved += cand.latencyMs / int64(cand.count) * repeats
+	}
+
+	p := report.Projected
+	p.TotalEntries = int64(report.Warmed)
+	if total := p.Hits + p.Misses; total > 0 {
+		p.HitRate = float64(p.Hits) / float64(total)
+	}
+	if p.Hits > 0 {
+		p.AvgLatencySavedMs = latencySaved / p.Hits
+	}
+
+	w.mu.Lock()
+	w.last = report
+	w.mu.Unlock()
+	return report, nil
+}
+
+// Last returns the report of the most recent warming pass, or nil
+func (w *Warmer) Last() *WarmReport {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.last
+}
+
+// Start warms the cache now and then every Interval until ctx is done or
+// the warmer is closed
+func (w *Warmer) Start(ctx context.Context) {
+	w.mu.Lock()
+	if w.stop != nil {
+		w.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	w.stop = stop
+	w.mu.Unlock()
+
+	go func() {
+		w.warmAndLog(ctx)
+		if w.config.Interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				w.warmAndLog(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops scheduled warming
+func (w *Warmer) Close() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
+
+func (w *Warmer) warmAndLog(ctx context.Context) {
+	report, err := w.Warm(ctx)
+	if err != nil {
+		log.Printf("[Cache] Warming failed: %v", err)
+		return
+	}
+	log.Printf("[Cache] Warmed %d of %d repeated prompts from %d requests; projected %d tokens ($%.2f) saved",
+		report.Warmed, report.Candidates, report.Scanned, report.Projected.TokensSaved, report.Projected.CostSavedUSD)
+}
+
+// candidates groups successful logged requests by provider, model and
+// canonical request body, keeping those that repeat enough
+func (w *Warmer) candidates(logs []*analytics.RequestLog) []*warmCandidate {
+	groups := make(map[string]*warmCandidate)
+	for _, l := range logs {
+		if l.StatusCode >= 400 || l.RequestBody == "" || truncated(l.RequestBody) {
+			continue
+		}
+		body := provider.CanonicalizeRequestBody(l.RequestBody)
+		id := l.ProviderID + "\x00" + l.ModelName + "\x00" + body
+		cand, ok := groups[id]
+		if !ok {
+			cand = &warmCandidate{providerID: l.ProviderID, modelName: l.ModelName, requestBody: body}
+			groups[id] = cand
+		}
+		cand.count++
+		cand.totalTokens += l.TotalTokens
+		cand.totalCost += l.CostUSD
+		cand.latencyMs += l.LatencyMs
+		if l.ResponseBody != "" && !truncated(l.ResponseBody) && !l.Timestamp.Before(cand.lastSeen) {
+			cand.response = l.ResponseBody
+			cand.lastSeen = l.Timestamp
+		}
+	}
+
+	out := make([]*warmCandidate, 0, len(groups))
+	for _, cand := range groups {
+		if cand.count >= w.config.MinOccurrences && cand.response != "" {
+			out = append(out, cand)
+		}
+	}
+	return out
+}
+
+// rank orders candidates by the configured strategy, most valuable first
+func (w *Warmer) rank(cands []*warmCandidate) []*warmCandidate {
+	sort.Slice(cands, func(i, j int) bool {
+		a, b := cands[i], cands[j]
+		if w.config.Strategy == WarmByCost && a.totalCost != b.totalCost {
+			return a.totalCost > b.totalCost
+		}
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		if a.totalCost != b.totalCost {
+			return a.totalCost > b.totalCost
+		}
+		return a.requestBody < b.requestBody
+	})
+	return cands
+}
+
+// truncated reports whether the analytics logger cut a body short, which
+// makes it unusable as a cached request or response
+func truncated(body string) bool {
+	return strings.HasSuffix(body, "... [truncated]")
+}
+
+// jsonOrString returns s as raw JSON when it is valid JSON, so warmed keys
+// and responses match those of live requests
+func jsonOrString(s string) interface{} {
+	if json.Valid([]byte(s)) {
+		return json.RawMessage(s)
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+)
+
+func warmLog(body, response string, tokens int64, cost float64) *analytics.RequestLog {
+	return &analytics.RequestLog{
+		Timestamp:    time.Now(),
+		ProviderID:   "openai",
+		ModelName:    "gpt-4",
+		TotalTokens:  tokens,
+		LatencyMs:    400,
+		StatusCode:   200,
+		CostUSD:      cost,
+		RequestBody:  body,
+		ResponseBody: response,
+	}
+}
+
+func TestWarmer_WarmsMostRepeatedPrompts(t *testing.T) {
+	ping := `{"messages":[{"role":"user","content":"ping"}]}`
+	essay := `{"messages":[{"role":"user","content":"write an essay"}]}`
+	storage := &mockStorage{logs: []*analytics.RequestLog{
+		warmLog(ping, `{"content":"pong"}`, 10, 0.001),
+		warmLog(ping, `{"content":"pong"}`, 10, 0.001),
+		warmLog(ping, `{"content":"pong"}`, 10, 0.001),
+		warmLog(essay, `{"content":"essay"}`, 1000, 0.5),
+		warmLog(essay, "", 1000, 0.5),
+		warmLog(`{"messages":[]}`, `{}`, 5, 0),                      // Seen once
+		warmLog(`{"failed":true}`, `{"a":"b"... [truncated]`, 5, 0), // Truncated response
+		{ProviderID: "openai", StatusCode: 500},                     // Failed
+	}}
+	c := New(&Config{Enabled: true, DefaultTTL: time.Hour, MaxSize: 10})
+	w := NewWarmer(storage, c, &WarmConfig{MaxEntries: 1})
+
+	report, err := w.Warm(context.Background())
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if report.Scanned != 8 || report.Candidates != 2 || report.Warmed != 1 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	key, _ := GenerateKey("openai", "gpt-4", json.RawMessage(ping))
+	entry, ok := c.Get(context.Background(), key)
+	if !ok {
+		t.Fatal("expected the most repeated prompt to be warmed")
+	}
+	if entry.Metadata["warmed"] != true || entry.TokensSaved != 10 {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+	if string(entry.Response.(json.RawMessage)) != `{"content":"pong"}` {
+		t.Errorf("unexpected response %v", entry.Response)
+	}
+
+	p := report.Projected
+	if p.Hits != 2 || p.Misses != 1 || p.TokensSaved != 20 || p.AvgLatencySavedMs != 400 || p.TotalEntries != 1 {
+		t.Errorf("unexpected projection %+v", p)
+	}
+	if w.Last() != report {
+		t.Error("expected the report to be kept")
+	}
+}
+
+func TestWarmer_CostStrategy(t *testing.T) {
+	ping := `{"messages":[{"role":"user","content":"ping"}]}`
+	essay := `{"messages":[{"role":"user","content":"write an essay"}]}`
+	storage := &mockStorage{logs: []*analytics.RequestLog{
+		warmLog(ping, "pong", 10, 0.001),
+		warmLog(ping, "pong", 10, 0.001),
+		warmLog(ping, "pong", 10, 0.001),
+		warmLog(essay, "essay", 1000, 0.5),
+		warmLog(essay, "essay", 1000, 0.5),
+	}}
+	c := New(&Config{Enabled: true, DefaultTTL: time.Hour, MaxSize: 10})
+	report, err := NewWarmer(storage, c, &WarmConfig{MaxEntries: 1, Strategy: WarmByCost}).Warm(context.Background())
+	if err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if report.Projected.TokensSaved != 1000 || report.Projected.CostSavedUSD != 0.5 {
+		t.Errorf("expected the costliest prompt to be warmed, got %+v", report.Projected)
+	}
+
+	if _, err := NewWarmer(storage, c, &WarmConfig{Strategy: "random"}).Warm(context.Background()); err == nil {
+		t.Error("expected an unknown strategy to fail")
+	}
+}
+
+func TestWarmer_SetsBackend(t *testing.T) {
+	backend := newMockBackend()
+	c := &Cache{backend: backend, config: DefaultConfig(), stats: &Stats{}}
+	storage := &mockStorage{logs: []*analytics.RequestLog{
+		warmLog(`{"q":1}`, "a", 10, 0.01),
+		warmLog(`{"q":1}`, "a", 10, 0.01),
+	}}
+	if _, err := NewWarmer(storage, c, nil).Warm(context.Background()); err != nil {
+		t.Fatalf("Warm failed: %v", err)
+	}
+	if backend.setCount != 1 {
+		t.Errorf("expected the entry to reach the backend, got %d sets", backend.setCount)
+	}
+}
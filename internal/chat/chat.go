@@ -0,0 +1,390 @@
+// Package chat lets a human question a project's codebase through an agent
+// that has the read-only tools of a dispatched agent: reading files and
+// directory trees, searching text and reading git history. Nothing is
+// written and no beads are filed; a session is a conversation kept in
+// memory until it has been idle for a while.
+package chat
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// Tools are the actions a chat agent may take. None of them change the
+// project.
+var Tools = []string{
+	actions.ActionReadFile,
+	actions.ActionReadTree,
+	actions.ActionSearchText,
+	actions.ActionGitLog,
+	actions.ActionGitStatus,
+	actions.ActionGitDiff,
+	actions.ActionGitListBranches,
+}
+
+// Executor runs a chat agent's actions against its project.
+// *actions.Router implements it.
+type Executor interface {
+	Execute(ctx context.Context, env *actions.ActionEnvelope, actx actions.ActionContext) ([]actions.Result, error)
+}
+
+// Completer sends a conversation to a model and returns its reply. When
+// onChunk is not nil, pieces of the reply are passed to it as they arrive.
+type Completer func(ctx context.Context, messages []provider.ChatMessage, onChunk func(string)) (string, error)
+
+// Event types reported while a message is answered
+const (
+	EventChunk      = "chunk"       // A piece of the model's reply as it streams
+	EventToolCall   = "tool_call"   // The agent asked to run a tool
+	EventToolResult = "tool_result" // A tool ran, or was refused
+	EventMessage    = "message"     // The agent's answer
+)
+
+// Event is a step in answering a message
+type Event struct {
+	Type    string          `json:"type"`
+	Content string          `json:"content,omitempty"`
+	Action  *actions.Action `json:"action,omitempty"`
+	Result  *actions.Result `json:"result,omitempty"`
+}
+
+// ToolCall is a tool the agent ran while answering, with its result
+type ToolCall struct {
+	Action actions.Action `json:"action"`
+	Result actions.Result `json:"result"`
+}
+
+// Reply is the agent's answer to a message
+type Reply struct {
+	SessionID string     `json:"session_id"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls"`
+	Rounds    int        `json:"rounds"` // Model calls made to answer
+}
+
+// Message is a turn of a session's transcript
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Session is a conversation with a project's chat agent
+type Session struct {
+	ID        string    `json:"id"`
+	ProjectID string    `json:"project_id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Messages  []Message `json:"messages"` // Without the system prompt
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	turn     sync.Mutex // Held while a message is answered
+	mu       sync.Mutex // Guards the messages and UpdatedAt
+	messages []provider.ChatMessage
+}
+
+// Config configures chat sessions
+type Config struct {
+	MaxToolRounds int           // Model calls running tools per message; default 8
+	IdleTimeout   time.Duration // Idle time after which a session is dropped; default 1h
+	MaxSessions   int           // Sessions kept at once, the least recently used dropped first; default 100
+	MaxMessages   int           // Messages after the system prompt sent to the model; default 40
+}
+
+// DefaultConfig returns the defaults Config describes
+func DefaultConfig() Config {
+	return Config{MaxToolRounds: 8, IdleTimeout: time.Hour, MaxSessions: 100, MaxMessages: 40}
+}
+
+// Manager keeps the chat sessions
+type Manager struct {
+	cfg      Config
+	mu       sync.Mutex
+	sessions map[string]*Session
+	now      func() time.Time
+}
+
+// NewManager returns a manager with cfg's unset fields defaulted
+func NewManager(cfg Config) *Manager {
+	def := DefaultConfig()
+	if cfg.MaxToolRounds <= 0 {
+		cfg.MaxToolRounds = def.MaxToolRounds
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = def.IdleTimeout
+	}
+	if cfg.MaxSessions <= 0 {
+		cfg.MaxSessions = def.MaxSessions
+	}
+	if cfg.MaxMessages <= 0 {
+		cfg.MaxMessages = def.MaxMessages
+	}
+	return &Manager{cfg: cfg, sessions: make(map[string]*Session), now: time.Now}
+}
+
+// Start opens a session of a user with a project's chat agent
+func (m *Manager) Start(projectID, userID, systemPrompt string) *Session {
+	now := m.now()
+	s := &Session{
+		ID:        uuid.New().String(),
+		ProjectID: projectID,
+		UserID:    userID,
+		Messages:  []Message{},
+		CreatedAt: now,
+		UpdatedAt: now,
+		messages:  []provider.ChatMessage{{Role: "system", Content: systemPrompt}},
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expireLocked(now)
+	if len(m.sessions) >= m.cfg.MaxSessions {
+		m.evictLocked(len(m.sessions) - m.cfg.MaxSessions + 1)
+	}
+	m.sessions[s.ID] = s
+	return s
+}
+
+// Get returns a session that has not expired
+func (m *Manager) Get(id string) (*Session, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expireLocked(m.now())
+	s, ok := m.sessions[id]
+	return s, ok
+}
+
+// Delete ends a session, reporting whether it existed
+func (m *Manager) Delete(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.sessions[id]
+	delete(m.sessions, id)
+	return ok
+}
+
+// expireLocked drops the sessions idle past the timeout
+func (m *Manager) expireLocked(now time.Time) {
+	for id, s := range m.sessions {
+		if now.Sub(s.lastUsed()) > m.cfg.IdleTimeout {
+			delete(m.sessions, id)
+		}
+	}
+}
+
+// evictLocked drops the n least recently used sessions
+func (m *Manager) evictLocked(n int) {
+	list := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].lastUsed().Before(list[j].lastUsed()) })
+	for i := 0; i < n && i < len(list); i++ {
+		delete(m.sessions, list[i].ID)
+	}
+}
+
+// Snapshot returns a copy of the session safe to encode while it is in use
+func (s *Session) Snapshot() *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Session{
+		ID:        s.ID,
+		ProjectID: s.ProjectID,
+		UserID:    s.UserID,
+		Messages:  append([]Message(nil), s.Messages...),
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+}
+
+func (s *Session) lastUsed() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.UpdatedAt
+}
+
+// Ask answers a message in a session. The agent's replies are parsed for
+// actions; tools among them run through exec and their results go back to
+// the model, other actions are refused, until a reply has no actions or
+// the tool rounds run out. onEvent, when not nil, is told of each step.
+// Messages of one session are answered one at a time.
+func (m *Manager) Ask(ctx context.Context, s *Session, message string, complete Completer, exec Executor, onEvent func(Event)) (*Reply, error) {
+	if strings.TrimSpace(message) == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+	emit := func(e Event) {
+		if onEvent != nil {
+			onEvent(e)
+		}
+	}
+	var onChunk func(string)
+	if onEvent != nil {
+		onChunk = func(text string) { emit(Event{Type: EventChunk, Content: text}) }
+	}
+
+	s.turn.Lock()
+	defer s.turn.Unlock()
+	s.add("user", message, true, m.now())
+
+	reply := &Reply{SessionID: s.ID, ToolCalls: []ToolCall{}}
+	for {
+		last := reply.Rounds == m.cfg.MaxToolRounds
+		if last {
+			s.add("user", "You have used all your tool calls for this question. Answer now from what you have found, without actions.", false, m.now())
+		}
+		text, err := complete(ctx, m.window(s.conversation()), onChunk)
+		if err != nil {
+			return nil, err
+		}
+		reply.Rounds++
+		s.add("assistant", text, true, m.now())
+
+		env, err := actions.DecodeLenient([]byte(text))
+		if last || err != nil || env == nil || len(env.Actions) == 0 || onlyDone(env) {
+			reply.Content = answer(text, env)
+			emit(Event{Type: EventMessage, Content: reply.Content})
+			return reply, nil
+		}
+
+		results := runTools(ctx, env, s.ProjectID, exec)
+		for i, a := range env.Actions {
+			a, r := a, results[i]
+			emit(Event{Type: EventToolCall, Action: &a})
+			emit(Event{Type: EventToolResult, Action: &a, Result: &r})
+			reply.ToolCalls = append(reply.ToolCalls, ToolCall{Action: a, Result: r})
+		}
+		s.add("user", actions.FormatResultsAsUserMessage(results), false, m.now())
+	}
+}
+
+// add appends a message to the conversation, and to the transcript when
+// shown; tool results and notes to the model are not
+func (s *Session) add(role, content string, shown bool, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, provider.ChatMessage{Role: role, Content: content})
+	if shown {
+		s.Messages = append(s.Messages, Message{Role: role, Content: content})
+	}
+	s.UpdatedAt = now
+}
+
+// conversation returns a copy of the messages sent to the model
+func (s *Session) conversation() []provider.ChatMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]provider.ChatMessage(nil), s.messages...)
+}
+
+// window is the system prompt and the most recent messages, starting at a
+// user message
+func (m *Manager) window(messages []provider.ChatMessage) []provider.ChatMessage {
+	if len(messages)-1 <= m.cfg.MaxMessages {
+		return messages
+	}
+	start := len(messages) - m.cfg.MaxMessages
+	for start < len(messages)-1 && messages[start].Role != "user" {
+		start++
+	}
+	return append([]provider.ChatMessage{messages[0]}, messages[start:]...)
+}
+
+// runTools runs the tools among a reply's actions and refuses the rest,
+// returning a result per action in order
+func runTools(ctx context.Context, env *actions.ActionEnvelope, projectID string, exec Executor) []actions.Result {
+	results := make([]actions.Result, len(env.Actions))
+	allowed := &actions.ActionEnvelope{}
+	var at []int
+	for i, a := range env.Actions {
+		switch {
+		case !IsTool(a.Type):
+			results[i] = actions.Result{ActionType: a.Type, Status: "error",
+				Message: fmt.Sprintf("%s is not available in read-only chat; use one of %s", a.Type, strings.Join(Tools, ", "))}
+		case exec == nil:
+			results[i] = actions.Result{ActionType: a.Type, Status: "error", Message: "tools are not available"}
+		default:
+			allowed.Actions = append(allowed.Actions, a)
+			at = append(at, i)
+		}
+	}
+	if len(allowed.Actions) == 0 {
+		return results
+	}
+	ran, err := exec.Execute(ctx, allowed, actions.ActionContext{ProjectID: projectID})
+	for j, i := range at {
+		switch {
+		case err != nil:
+			results[i] = actions.Result{ActionType: env.Actions[i].Type, Status: "error", Message: err.Error()}
+		case j < len(ran):
+			results[i] = ran[j]
+		}
+	}
+	return results
+}
+
+// IsTool reports whether an action is one of the chat agent's tools
+func IsTool(actionType string) bool {
+	for _, t := range Tools {
+		if t == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyDone reports whether a reply's only actions finish the task
+func onlyDone(env *actions.ActionEnvelope) bool {
+	for _, a := range env.Actions {
+		if a.Type != actions.ActionDone {
+			return false
+		}
+	}
+	return true
+}
+
+// answer is the text of a reply without actions, or the notes of one
+// that only finished
+func answer(text string, env *actions.ActionEnvelope) string {
+	if env != nil && len(env.Actions) > 0 && env.Notes != "" {
+		return env.Notes
+	}
+	return strings.TrimSpace(text)
+}
+
+// SystemPrompt is the chat agent's prompt for a project
+func SystemPrompt(projectName, gitRepo, branch string) string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("You are answering an engineer's questions about the %s project", projectName))
+	if gitRepo != "" {
+		sb.WriteString(fmt.Sprintf(" (%s, branch %s)", gitRepo, branch))
+	}
+	sb.WriteString(`.
+
+Look at the code before answering. To run tools, reply with only a JSON
+object of actions; their results come back in the next message:
+
+{"actions": [{"type": "read_file", "path": "cmd/main.go"}]}
+
+Tools, all read-only:
+- read_file: {"type": "read_file", "path": "..."}
+- read_tree: {"type": "read_tree", "path": ".", "max_depth": 2}
+- search_text: {"type": "search_text", "query": "...", "path": "."}
+- git_log: {"type": "git_log", "branch": "main", "max_count": 20}
+- git_status: {"type": "git_status"}
+- git_diff: {"type": "git_diff"}
+- git_list_branches: {"type": "git_list_branches"}
+
+You cannot change files, run commands or file beads. When you have what
+you need, answer in plain prose or markdown with no JSON, citing file
+paths and line numbers where they help.
+`)
+	return sb.String()
+}
@@ -0,0 +1,138 @@
+package chat
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// scripted replies with each of its replies in turn, repeating the last
+func scripted(replies ...string) (Completer, *[][]provider.ChatMessage) {
+	var seen [][]provider.ChatMessage
+	return func(ctx context.Context, messages []provider.ChatMessage, onChunk func(string)) (string, error) {
+		seen = append(seen, messages)
+		reply := replies[len(replies)-1]
+		if len(seen) <= len(replies) {
+			reply = replies[len(seen)-1]
+		}
+		if onChunk != nil {
+			onChunk(reply)
+		}
+		return reply, nil
+	}, &seen
+}
+
+// recordingExecutor runs every action successfully, recording their types
+type recordingExecutor struct {
+	ran []string
+}
+
+func (e *recordingExecutor) Execute(ctx context.Context, env *actions.ActionEnvelope, actx actions.ActionContext) ([]actions.Result, error) {
+	results := make([]actions.Result, len(env.Actions))
+	for i, a := range env.Actions {
+		e.ran = append(e.ran, a.Type+":"+actx.ProjectID)
+		results[i] = actions.Result{ActionType: a.Type, Status: "executed", Message: "ok",
+			Metadata: map[string]interface{}{"path": a.Path, "content": "package main"}}
+	}
+	return results, nil
+}
+
+func TestAsk_RunsToolsAndRefusesWrites(t *testing.T) {
+	m := NewManager(Config{})
+	s := m.Start("proj-1", "u-1", SystemPrompt("Demo", "", ""))
+	complete, seen := scripted(
+		`{"actions": [{"type": "read_file", "path": "main.go"}, {"type": "write_file", "path": "main.go", "content": "x"}]}`,
+		"main.go declares package main.",
+	)
+	exec := &recordingExecutor{}
+	var events []string
+
+	reply, err := m.Ask(context.Background(), s, "What package is main.go?", complete, exec, func(e Event) {
+		events = append(events, e.Type)
+	})
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if reply.Content != "main.go declares package main." || reply.Rounds != 2 {
+		t.Errorf("unexpected reply %+v", reply)
+	}
+	if len(exec.ran) != 1 || exec.ran[0] != "read_file:proj-1" {
+		t.Errorf("expected only read_file run in the project, got %v", exec.ran)
+	}
+	if len(reply.ToolCalls) != 2 || reply.ToolCalls[1].Result.Status != "error" ||
+		!strings.Contains(reply.ToolCalls[1].Result.Message, "read-only") {
+		t.Errorf("expected write_file refused, got %+v", reply.ToolCalls)
+	}
+	if last := (*seen)[1]; !strings.Contains(last[len(last)-1].Content, "Action Results") {
+		t.Errorf("expected the tool results sent back to the model, got %q", last[len(last)-1].Content)
+	}
+	if events[0] != EventChunk || events[len(events)-1] != EventMessage {
+		t.Errorf("unexpected events %v", events)
+	}
+
+	// The transcript leaves out tool results
+	snap := s.Snapshot()
+	if len(snap.Messages) != 3 || snap.Messages[0].Role != "user" || snap.Messages[2].Content != reply.Content {
+		t.Errorf("unexpected transcript %+v", snap.Messages)
+	}
+}
+
+func TestAsk_StopsAfterMaxToolRounds(t *testing.T) {
+	m := NewManager(Config{MaxToolRounds: 2})
+	s := m.Start("proj-1", "", "system")
+	complete, seen := scripted(`{"actions": [{"type": "git_log", "max_count": 5}]}`)
+
+	reply, err := m.Ask(context.Background(), s, "What changed?", complete, &recordingExecutor{}, nil)
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if reply.Rounds != 3 || len(reply.ToolCalls) != 2 {
+		t.Errorf("expected two tool rounds and a final answer, got %d rounds and %d calls", reply.Rounds, len(reply.ToolCalls))
+	}
+	if last := (*seen)[2]; !strings.Contains(last[len(last)-1].Content, "used all your tool calls") {
+		t.Errorf("expected the model told to answer, got %q", last[len(last)-1].Content)
+	}
+}
+
+func TestManager_ExpiresAndEvictsSessions(t *testing.T) {
+	now := time.Now()
+	m := NewManager(Config{IdleTimeout: time.Hour, MaxSessions: 2})
+	m.now = func() time.Time { return now }
+
+	first := m.Start("proj-1", "", "system")
+	now = now.Add(time.Minute)
+	second := m.Start("proj-1", "", "system")
+	now = now.Add(time.Minute)
+	m.Start("proj-1", "", "system")
+	if _, ok := m.Get(first.ID); ok {
+		t.Error("expected the least recently used session evicted")
+	}
+	if _, ok := m.Get(second.ID); !ok {
+		t.Fatal("expected the second session kept")
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, ok := m.Get(second.ID); ok {
+		t.Error("expected an idle session to expire")
+	}
+	if m.Delete(second.ID) {
+		t.Error("expected an expired session gone")
+	}
+}
+
+func TestWindow_KeepsSystemPromptAndStartsAtUser(t *testing.T) {
+	m := NewManager(Config{MaxMessages: 3})
+	messages := []provider.ChatMessage{
+		{Role: "system", Content: "s"},
+		{Role: "user", Content: "u1"}, {Role: "assistant", Content: "a1"},
+		{Role: "user", Content: "u2"}, {Role: "assistant", Content: "a2"},
+	}
+	got := m.window(messages)
+	if len(got) != 3 || got[0].Content != "s" || got[1].Content != "u2" {
+		t.Errorf("unexpected window %+v", got)
+	}
+}
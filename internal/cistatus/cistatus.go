@@ -0,0 +1,484 @@
+// Package cistatus tracks the CI checks that run on bead branches. Check
+// results arrive from GitHub and GitLab webhooks or from polling the git
+// host, and are kept per branch with the commit they ran on. Pull requests
+// are not opened or merged until the branch's required checks pass, and a
+// branch that fails on several commits in a row is reported so the
+// failures can be turned into lessons.
+package cistatus
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Check and branch states
+const (
+	StatePending = "pending" // Running, queued or not reported yet
+	StateSuccess = "success"
+	StateFailure = "failure"
+	StateNone    = "none" // No checks reported and none required
+)
+
+// maxFailures bounds the failed commits kept with a status
+const maxFailures = 10
+
+// staleAfter is how long an unfinished status keeps being polled without
+// any change
+const staleAfter = 24 * time.Hour
+
+// Check is one CI check run, commit status or pipeline job
+type Check struct {
+	Name  string `json:"name"`
+	State string `json:"state"` // pending, success or failure
+	URL   string `json:"url,omitempty"`
+}
+
+// Failure is a commit whose checks failed
+type Failure struct {
+	SHA    string    `json:"sha"`
+	Checks []string  `json:"checks"` // Names of the failed checks
+	At     time.Time `json:"at"`
+}
+
+// Status is the CI status of a branch's head commit
+type Status struct {
+	ProjectID string    `json:"project_id"`
+	Branch    string    `json:"branch"`
+	BeadID    string    `json:"bead_id,omitempty"`
+	SHA       string    `json:"sha,omitempty"`
+	State     string    `json:"state"` // Over the required checks
+	Checks    []Check   `json:"checks"`
+	Failures  []Failure `json:"failures,omitempty"` // Commits in a row whose checks failed, oldest first
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Failed names the failed checks
+func (s *Status) Failed() []string {
+	return s.named(StateFailure)
+}
+
+// Pending names the checks that have not finished
+func (s *Status) Pending() []string {
+	return s.named(StatePending)
+}
+
+func (s *Status) named(state string) []string {
+	var names []string
+	for _, c := range s.Checks {
+		if c.State == state {
+			names = append(names, c.Name)
+		}
+	}
+	return names
+}
+
+// Report is a set of check results for a commit of a branch
+type Report struct {
+	ProjectID string
+	Branch    string
+	BeadID    string // Bead working the branch, if known
+	SHA       string
+	Checks    []Check
+	Complete  bool // Checks are every check of the commit, as polling returns; otherwise they are merged by name
+}
+
+// Store persists branch statuses
+type Store interface {
+	// SaveCIStatus inserts or replaces a branch's status
+	SaveCIStatus(s *Status) error
+	// GetCIStatus returns a branch's status, or nil if unknown
+	GetCIStatus(projectID, branch string) (*Status, error)
+	// ListCIStatuses returns the statuses of a project's branches, or of
+	// every project's when projectID is empty, most recently updated first
+	ListCIStatuses(projectID string) ([]*Status, error)
+}
+
+// Poller asks the git host for the checks on a branch
+type Poller interface {
+	// Checks returns the head commit of branch and the checks reported on it
+	Checks(ctx context.Context, projectID, branch string) (sha string, checks []Check, err error)
+}
+
+// Config tunes CI tracking
+type Config struct {
+	Enabled         bool          // Track CI and gate pull requests on it
+	RequiredChecks  []string      // Checks that must pass; empty requires every reported check
+	PollInterval    time.Duration // 0 relies on webhooks
+	LessonThreshold int           // Failed commits in a row reported as repeated failures
+}
+
+// DefaultConfig reports a branch after two failed commits in a row
+func DefaultConfig() Config {
+	return Config{LessonThreshold: 2}
+}
+
+// Manager tracks branch statuses. Gate allows everything on a nil Manager.
+type Manager struct {
+	store  Store
+	poller Poller
+	cfg    Config
+
+	mu                sync.Mutex
+	required          map[string][]string // Required checks by project
+	onChange          func(s *Status, previous string)
+	onRepeatedFailure func(s *Status)
+	stop              chan struct{}
+}
+
+// NewManager creates a manager backed by store. poller may be nil, in
+// which case statuses only come from reports.
+func NewManager(store Store, poller Poller, cfg Config) *Manager {
+	if store == nil {
+		return nil
+	}
+	if cfg.LessonThreshold <= 0 {
+		cfg.LessonThreshold = DefaultConfig().LessonThreshold
+	}
+	return &Manager{store: store, poller: poller, cfg: cfg, required: make(map[string][]string)}
+}
+
+// Config returns the configuration the manager runs with
+func (m *Manager) Config() Config {
+	return m.cfg
+}
+
+// Enabled reports whether CI is tracked
+func (m *Manager) Enabled() bool {
+	return m != nil && m.cfg.Enabled
+}
+
+// SetRequiredChecks replaces the required checks for a single project
+func (m *Manager) SetRequiredChecks(projectID string, checks []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.required[projectID] = checks
+}
+
+// RequiredChecks returns the checks that must pass in a project
+func (m *Manager) RequiredChecks(projectID string) []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if checks, ok := m.required[projectID]; ok {
+		return checks
+	}
+	return m.cfg.RequiredChecks
+}
+
+// OnChange registers the function told when a branch's state or head
+// commit changes, with the state it had before
+func (m *Manager) OnChange(fn func(s *Status, previous string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onChange = fn
+}
+
+// OnRepeatedFailure registers the function told when a branch has failed
+// on LessonThreshold commits in a row
+func (m *Manager) OnRepeatedFailure(fn func(s *Status)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRepeatedFailure = fn
+}
+
+// Get returns a branch's status, or nil if nothing was reported for it
+func (m *Manager) Get(projectID, branch string) (*Status, error) {
+	return m.store.GetCIStatus(projectID, branch)
+}
+
+// ForBead returns the statuses of a bead's branches, most recently updated
+// first
+func (m *Manager) ForBead(projectID, beadID string) ([]*Status, error) {
+	all, err := m.store.ListCIStatuses(projectID)
+	if err != nil {
+		return nil, err
+	}
+	var out []*Status
+	for _, s := range all {
+		if s.BeadID == beadID {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+// Track starts tracking a branch an agent just pushed. Its checks are
+// cleared until CI reports on the new commit.
+func (m *Manager) Track(projectID, beadID, branch string) (*Status, error) {
+	if !m.Enabled() {
+		return nil, nil
+	}
+	return m.update(projectID, branch, func(s *Status) {
+		if beadID != "" {
+			s.BeadID = beadID
+		}
+		s.SHA = ""
+		s.Checks = nil
+	})
+}
+
+// Report records check results for a branch. Results for a new commit
+// replace those of the previous one. Reports are ignored while CI tracking
+// is disabled.
+func (m *Manager) Report(ctx context.Context, r Report) (*Status, error) {
+	if !m.Enabled() {
+		return nil, nil
+	}
+	if r.ProjectID == "" || r.Branch == "" {
+		return nil, fmt.Errorf("project_id and branch must not be empty")
+	}
+	for _, c := range r.Checks {
+		switch c.State {
+		case StatePending, StateSuccess, StateFailure:
+		default:
+			return nil, fmt.Errorf("invalid state %q for check %s", c.State, c.Name)
+		}
+	}
+	return m.update(r.ProjectID, r.Branch, func(s *Status) {
+		if r.BeadID != "" {
+			s.BeadID = r.BeadID
+		}
+		if r.SHA != "" && r.SHA != s.SHA {
+			s.SHA = r.SHA
+			s.Checks = nil
+		}
+		if r.Complete {
+			s.Checks = slices.Clone(r.Checks)
+			return
+		}
+		for _, c := range r.Checks {
+			i := slices.IndexFunc(s.Checks, func(old Check) bool { return old.Name == c.Name })
+			if i < 0 {
+				s.Checks = append(s.Checks, c)
+			} else {
+				s.Checks[i] = c
+			}
+		}
+	})
+}
+
+// Refresh polls the git host for a branch's checks
+func (m *Manager) Refresh(ctx context.Context, projectID, branch string) (*Status, error) {
+	if !m.Enabled() {
+		return nil, fmt.Errorf("CI tracking is disabled")
+	}
+	if m.poller == nil {
+		return nil, fmt.Errorf("no CI poller configured")
+	}
+	sha, checks, err := m.poller.Checks(ctx, projectID, branch)
+	if err != nil {
+		return nil, err
+	}
+	return m.Report(ctx, Report{ProjectID: projectID, Branch: branch, SHA: sha, Checks: checks, Complete: true})
+}
+
+// Gate returns an error unless the branch's required checks have passed.
+// It polls the git host first when it can. A branch no check reported on
+// passes when the project requires no particular checks.
+func (m *Manager) Gate(ctx context.Context, projectID, branch string) error {
+	if !m.Enabled() {
+		return nil
+	}
+	if m.poller != nil {
+		if _, err := m.Refresh(ctx, projectID, branch); err != nil {
+			log.Printf("[CI] Failed to poll checks for %s in %s: %v", branch, projectID, err)
+		}
+	}
+	s, err := m.store.GetCIStatus(projectID, branch)
+	if err != nil {
+		return fmt.Errorf("failed to read CI status of %s: %w", branch, err)
+	}
+	if s == nil {
+		if required := m.RequiredChecks(projectID); len(required) > 0 {
+			return fmt.Errorf("required CI checks have not reported on %s: %s", branch, strings.Join(required, ", "))
+		}
+		return nil
+	}
+	switch s.State {
+	case StateFailure:
+		return fmt.Errorf("CI failed on %s: %s", branch, strings.Join(s.Failed(), ", "))
+	case StatePending:
+		waiting := s.Pending()
+		for _, name := range m.RequiredChecks(projectID) {
+			if !slices.ContainsFunc(s.Checks, func(c Check) bool { return c.Name == name }) {
+				waiting = append(waiting, name)
+			}
+		}
+		return fmt.Errorf("CI checks on %s have not finished: %s", branch, strings.Join(waiting, ", "))
+	}
+	return nil
+}
+
+// Poll refreshes every branch whose checks have not finished and that
+// changed within the last day
+func (m *Manager) Poll(ctx context.Context) {
+	if !m.Enabled() || m.poller == nil {
+		return
+	}
+	statuses, err := m.store.ListCIStatuses("")
+	if err != nil {
+		log.Printf("[CI] Failed to list branches to poll: %v", err)
+		return
+	}
+	for _, s := range statuses {
+		if (s.State != StatePending && s.State != StateNone) || time.Since(s.UpdatedAt) > staleAfter {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if _, err := m.Refresh(ctx, s.ProjectID, s.Branch); err != nil {
+			log.Printf("[CI] Failed to poll checks for %s in %s: %v", s.Branch, s.ProjectID, err)
+		}
+	}
+}
+
+// Start polls every PollInterval until ctx is done or Close is called. It
+// does nothing without a poll interval or a poller.
+func (m *Manager) Start(ctx context.Context) {
+	if !m.Enabled() || m.poller == nil || m.cfg.PollInterval <= 0 {
+		return
+	}
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.Poll(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops polling
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// update applies change to a branch's status, re-evaluates its state and
+// saves it, telling the registered functions what changed
+func (m *Manager) update(projectID, branch string, change func(s *Status)) (*Status, error) {
+	m.mu.Lock()
+	prev, err := m.store.GetCIStatus(projectID, branch)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	s := &Status{ProjectID: projectID, Branch: branch}
+	if prev != nil {
+		cp := *prev
+		cp.Checks = slices.Clone(prev.Checks)
+		cp.Failures = slices.Clone(prev.Failures)
+		s = &cp
+	}
+	change(s)
+	s.State = evaluate(s.Checks, m.requiredLocked(projectID))
+
+	repeated := false
+	switch s.State {
+	case StateSuccess:
+		s.Failures = nil
+	case StateFailure:
+		failure := Failure{SHA: s.SHA, Checks: s.Failed(), At: time.Now().UTC()}
+		if n := len(s.Failures); n > 0 && s.SHA != "" && s.Failures[n-1].SHA == s.SHA {
+			s.Failures[n-1] = failure
+		} else {
+			s.Failures = append(s.Failures, failure)
+			repeated = len(s.Failures) == m.cfg.LessonThreshold
+			if len(s.Failures) > maxFailures {
+				s.Failures = s.Failures[len(s.Failures)-maxFailures:]
+			}
+		}
+	}
+
+	previous := ""
+	changed, notify := true, true
+	if prev != nil {
+		previous = prev.State
+		notify = prev.State != s.State || prev.SHA != s.SHA
+		changed = notify || prev.BeadID != s.BeadID || !slices.Equal(prev.Checks, s.Checks)
+	}
+	if changed {
+		s.UpdatedAt = time.Now().UTC()
+		if err := m.store.SaveCIStatus(s); err != nil {
+			m.mu.Unlock()
+			return nil, err
+		}
+	}
+	onChange, onRepeatedFailure := m.onChange, m.onRepeatedFailure
+	m.mu.Unlock()
+
+	if onChange != nil && notify {
+		onChange(s, previous)
+	}
+	if onRepeatedFailure != nil && repeated {
+		onRepeatedFailure(s)
+	}
+	return s, nil
+}
+
+func (m *Manager) requiredLocked(projectID string) []string {
+	if checks, ok := m.required[projectID]; ok {
+		return checks
+	}
+	return m.cfg.RequiredChecks
+}
+
+// evaluate returns the state of a commit over its required checks, or over
+// every check when none are required. A failed check fails the commit even
+// while others are still running.
+func evaluate(checks []Check, required []string) string {
+	relevant := checks
+	pending := false
+	if len(required) > 0 {
+		relevant = nil
+		for _, name := range required {
+			i := slices.IndexFunc(checks, func(c Check) bool { return c.Name == name })
+			if i < 0 {
+				pending = true
+				continue
+			}
+			relevant = append(relevant, checks[i])
+		}
+	} else if len(checks) == 0 {
+		return StateNone
+	}
+	for _, c := range relevant {
+		switch c.State {
+		case StateFailure:
+			return StateFailure
+		case StatePending:
+			pending = true
+		}
+	}
+	if pending {
+		return StatePending
+	}
+	return StateSuccess
+}
@@ -0,0 +1,44 @@
+package cistatus
+
+import "testing"
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name     string
+		checks   []Check
+		required []string
+		want     string
+	}{
+		{"nothing reported", nil, nil, StateNone},
+		{"all passed", []Check{{Name: "lint", State: StateSuccess}, {Name: "test", State: StateSuccess}}, nil, StateSuccess},
+		{"one running", []Check{{Name: "lint", State: StateSuccess}, {Name: "test", State: StatePending}}, nil, StatePending},
+		{"failure wins", []Check{{Name: "lint", State: StateFailure}, {Name: "test", State: StatePending}}, nil, StateFailure},
+		{"required missing", []Check{{Name: "lint", State: StateSuccess}}, []string{"test"}, StatePending},
+		{"optional failure ignored", []Check{{Name: "lint", State: StateFailure}, {Name: "test", State: StateSuccess}}, []string{"test"}, StateSuccess},
+	}
+	for _, tt := range tests {
+		if got := evaluate(tt.checks, tt.required); got != tt.want {
+			t.Errorf("%s: evaluate = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHostStates(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{GitHubCheckState("in_progress", ""), StatePending},
+		{GitHubCheckState("completed", "skipped"), StateSuccess},
+		{GitHubCheckState("completed", "timed_out"), StateFailure},
+		{GitHubStatusState("error"), StateFailure},
+		{GitHubStatusState("pending"), StatePending},
+		{GitLabJobState("running"), StatePending},
+		{GitLabJobState("canceled"), StateFailure},
+		{GitLabJobState("manual"), ""},
+	}
+	for i, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("case %d: got %q, want %q", i, tt.got, tt.want)
+		}
+	}
+}
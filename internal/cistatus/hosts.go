@@ -0,0 +1,43 @@
+package cistatus
+
+// GitHubCheckState maps a GitHub check run's status and conclusion to a
+// check state. Neutral and skipped runs count as passed.
+func GitHubCheckState(status, conclusion string) string {
+	if status != "completed" {
+		return StatePending
+	}
+	switch conclusion {
+	case "success", "neutral", "skipped":
+		return StateSuccess
+	default:
+		return StateFailure
+	}
+}
+
+// GitHubStatusState maps the state of a GitHub commit status to a check
+// state
+func GitHubStatusState(state string) string {
+	switch state {
+	case "success":
+		return StateSuccess
+	case "failure", "error":
+		return StateFailure
+	default:
+		return StatePending
+	}
+}
+
+// GitLabJobState maps the status of a GitLab pipeline or job to a check
+// state, or returns "" for jobs that did not and will not run on their own
+func GitLabJobState(status string) string {
+	switch status {
+	case "success":
+		return StateSuccess
+	case "failed", "canceled":
+		return StateFailure
+	case "skipped", "manual":
+		return ""
+	default:
+		return StatePending
+	}
+}
@@ -0,0 +1,148 @@
+package cistatus_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/cistatus"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "cistatus.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakePoller returns fixed checks for every branch
+type fakePoller struct {
+	sha    string
+	checks []cistatus.Check
+	polls  int
+}
+
+func (f *fakePoller) Checks(ctx context.Context, projectID, branch string) (string, []cistatus.Check, error) {
+	f.polls++
+	return f.sha, f.checks, nil
+}
+
+func TestReport_MergesChecksAndTracksFailures(t *testing.T) {
+	m := cistatus.NewManager(newTestDB(t), nil, cistatus.Config{Enabled: true})
+	var changes []string
+	m.OnChange(func(s *cistatus.Status, previous string) { changes = append(changes, previous+">"+s.State) })
+	var repeated *cistatus.Status
+	m.OnRepeatedFailure(func(s *cistatus.Status) { repeated = s })
+	ctx := context.Background()
+
+	report := func(sha string, checks ...cistatus.Check) *cistatus.Status {
+		t.Helper()
+		s, err := m.Report(ctx, cistatus.Report{ProjectID: "proj-1", Branch: "agent/bd-1/login", BeadID: "bd-1", SHA: sha, Checks: checks})
+		if err != nil {
+			t.Fatalf("Report failed: %v", err)
+		}
+		return s
+	}
+
+	report("a1", cistatus.Check{Name: "lint", State: cistatus.StateSuccess})
+	s := report("a1", cistatus.Check{Name: "test", State: cistatus.StateFailure})
+	if s.State != cistatus.StateFailure || len(s.Checks) != 2 || len(s.Failures) != 1 {
+		t.Fatalf("expected both checks and one failed commit, got %+v", s)
+	}
+	if repeated != nil {
+		t.Error("one failed commit is not a repeated failure")
+	}
+
+	// A new commit replaces the checks; failing again is repeated
+	s = report("b2", cistatus.Check{Name: "test", State: cistatus.StateFailure})
+	if len(s.Checks) != 1 || len(s.Failures) != 2 {
+		t.Fatalf("expected the new commit's checks and two failures, got %+v", s)
+	}
+	if repeated == nil || repeated.Failures[1].SHA != "b2" {
+		t.Fatalf("expected a repeated failure report, got %+v", repeated)
+	}
+
+	s = report("c3", cistatus.Check{Name: "test", State: cistatus.StateSuccess})
+	if s.State != cistatus.StateSuccess || len(s.Failures) != 0 {
+		t.Errorf("a passing commit should clear the failures, got %+v", s)
+	}
+	if got := strings.Join(changes, ","); got != ">success,success>failure,failure>failure,failure>success" {
+		t.Errorf("unexpected changes %s", got)
+	}
+
+	list, _ := m.ForBead("proj-1", "bd-1")
+	if len(list) != 1 || list[0].SHA != "c3" {
+		t.Errorf("ForBead = %+v", list)
+	}
+
+	if _, err := m.Report(ctx, cistatus.Report{ProjectID: "proj-1", Branch: "b", Checks: []cistatus.Check{{Name: "x", State: "skipped"}}}); err == nil {
+		t.Error("expected an unknown state to be refused")
+	}
+}
+
+func TestGate(t *testing.T) {
+	var nilManager *cistatus.Manager
+	if err := nilManager.Gate(context.Background(), "proj-1", "b"); err != nil {
+		t.Errorf("a nil manager gates nothing: %v", err)
+	}
+
+	poller := &fakePoller{sha: "a1", checks: []cistatus.Check{{Name: "test", State: cistatus.StatePending}}}
+	m := cistatus.NewManager(newTestDB(t), poller, cistatus.Config{Enabled: true})
+	ctx := context.Background()
+
+	if err := m.Gate(ctx, "proj-1", "agent/bd-1/x"); err == nil || !strings.Contains(err.Error(), "not finished: test") {
+		t.Errorf("expected a running check to hold the branch back, got %v", err)
+	}
+	poller.checks = []cistatus.Check{{Name: "test", State: cistatus.StateFailure}}
+	if err := m.Gate(ctx, "proj-1", "agent/bd-1/x"); err == nil || !strings.Contains(err.Error(), "CI failed") {
+		t.Errorf("expected a failed check to hold the branch back, got %v", err)
+	}
+	poller.checks = []cistatus.Check{{Name: "test", State: cistatus.StateSuccess}}
+	if err := m.Gate(ctx, "proj-1", "agent/bd-1/x"); err != nil {
+		t.Errorf("expected passing checks to let the branch through, got %v", err)
+	}
+	if poller.polls != 3 {
+		t.Errorf("expected Gate to poll each time, polled %d times", poller.polls)
+	}
+
+	webhooksOnly := cistatus.NewManager(newTestDB(t), nil, cistatus.Config{Enabled: true})
+	if err := webhooksOnly.Gate(ctx, "proj-1", "agent/bd-2/y"); err != nil {
+		t.Errorf("a branch without checks passes when none are required: %v", err)
+	}
+	webhooksOnly.SetRequiredChecks("proj-1", []string{"build"})
+	if err := webhooksOnly.Gate(ctx, "proj-1", "agent/bd-2/y"); err == nil {
+		t.Error("expected a required check that never reported to hold the branch back")
+	}
+}
+
+func TestTrackAndPoll(t *testing.T) {
+	poller := &fakePoller{sha: "a1", checks: []cistatus.Check{{Name: "test", State: cistatus.StatePending}}}
+	m := cistatus.NewManager(newTestDB(t), poller, cistatus.Config{Enabled: true})
+	ctx := context.Background()
+
+	s, err := m.Track("proj-1", "bd-1", "agent/bd-1/x")
+	if err != nil || s.State != cistatus.StateNone || s.BeadID != "bd-1" {
+		t.Fatalf("Track = %+v, %v", s, err)
+	}
+	m.Poll(ctx)
+	m.Poll(ctx)
+	poller.checks = []cistatus.Check{{Name: "test", State: cistatus.StateSuccess}}
+	m.Poll(ctx)
+	m.Poll(ctx)
+	if poller.polls != 3 {
+		t.Errorf("expected finished branches to stop being polled, polled %d times", poller.polls)
+	}
+	if s, _ := m.Get("proj-1", "agent/bd-1/x"); s.State != cistatus.StateSuccess || s.SHA != "a1" {
+		t.Errorf("unexpected status %+v", s)
+	}
+
+	disabled := cistatus.NewManager(newTestDB(t), poller, cistatus.Config{})
+	if s, err := disabled.Track("proj-1", "bd-1", "b"); s != nil || err != nil {
+		t.Errorf("a disabled manager tracks nothing, got %+v, %v", s, err)
+	}
+}
@@ -0,0 +1,262 @@
+// Package codechunk splits source files into syntactic chunks — functions,
+// methods, types and classes — so edits and embeddings work on whole
+// definitions rather than arbitrary runs of lines. Files are parsed with
+// tree-sitter; a definition the parser could not make sense of is left to
+// the surrounding code. Code outside any definition, and files in languages
+// without a grammar, are kept in blocks of at most MaxBlockLines lines.
+package codechunk
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+)
+
+// Kinds of chunk
+const (
+	KindFunction = "function"
+	KindMethod   = "method"
+	KindType     = "type"
+	KindClass    = "class"
+	KindBlock    = "block" // Code outside any definition
+)
+
+// MaxBlockLines is the most lines a block chunk holds
+const MaxBlockLines = 60
+
+// Chunk is a definition, or a run of code between definitions
+type Chunk struct {
+	Kind string `json:"kind"`
+	Name string `json:"name,omitempty"` // Methods are named Type.Method
+	// StartLine includes doc comments and decorators; DeclLine is where the
+	// definition itself starts. Lines are 1-based and inclusive.
+	StartLine int    `json:"start_line"`
+	DeclLine  int    `json:"decl_line"`
+	EndLine   int    `json:"end_line"`
+	Text      string `json:"text"`
+}
+
+// Lines is how many lines the chunk spans
+func (c Chunk) Lines() int {
+	return c.EndLine - c.StartLine + 1
+}
+
+// Split chunks content, picking the grammar from the extension of path
+func Split(path, content string) []Chunk {
+	lines := strings.Split(content, "\n")
+	var defs []Chunk
+	if g := grammarFor(path); g != nil {
+		defs = g.split(content, lines)
+	}
+	return fill(lines, defs)
+}
+
+// Supported reports whether files at path are parsed into definitions
+func Supported(path string) bool {
+	return grammarFor(path) != nil
+}
+
+// Find returns the definitions named name; a bare method name matches
+// Type.Method
+func Find(chunks []Chunk, name string) []Chunk {
+	var found []Chunk
+	for _, c := range chunks {
+		if c.Kind == KindBlock || c.Name == "" {
+			continue
+		}
+		if c.Name == name || strings.HasSuffix(c.Name, "."+name) {
+			found = append(found, c)
+		}
+	}
+	return found
+}
+
+// At returns the chunk holding line, or nil past the end
+func At(chunks []Chunk, line int) *Chunk {
+	for i := range chunks {
+		if chunks[i].StartLine <= line && line <= chunks[i].EndLine {
+			return &chunks[i]
+		}
+	}
+	return nil
+}
+
+func grammarFor(path string) *grammar {
+	return grammars[strings.ToLower(filepath.Ext(path))]
+}
+
+// split parses content and returns its definitions in order
+func (g *grammar) split(content string, lines []string) []Chunk {
+	parser := sitter.NewParser()
+	defer parser.Close()
+	parser.SetLanguage(g.language())
+	src := []byte(content)
+	tree, err := parser.ParseCtx(context.Background(), nil, src)
+	if err != nil {
+		return nil
+	}
+	defer tree.Close()
+
+	s := &splitter{g: g, src: src, lines: lines}
+	s.walk(tree.RootNode())
+	return s.defs
+}
+
+// splitter collects the definitions of one file
+type splitter struct {
+	g     *grammar
+	src   []byte
+	lines []string
+	defs  []Chunk
+}
+
+// walk collects the definitions among the children of n
+func (s *splitter) walk(n *sitter.Node) {
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		child := n.NamedChild(i)
+		def := s.g.unwrap(child)
+		switch {
+		case s.g.namespaces[def.Type()]:
+			if body := def.ChildByFieldName("body"); body != nil {
+				s.walk(body)
+			} else {
+				s.walk(def)
+			}
+		case child.HasError():
+			// Left to the surrounding block
+		case s.g.containers[def.Type()] && s.methods(def):
+			// Its methods were chunked on their own
+		default:
+			if kind, name := s.g.define(def, s.src); name != "" {
+				s.add(kind, name, child)
+			}
+		}
+	}
+}
+
+// methods adds the methods of the container def as chunks of their own,
+// reporting whether it has any. A container without methods stays whole.
+func (s *splitter) methods(def *sitter.Node) bool {
+	body := def.ChildByFieldName("body")
+	if body == nil {
+		body = childOfType(def, s.g.bodies)
+	}
+	if body == nil {
+		return false
+	}
+	_, container := s.g.define(def, s.src)
+	if container == "" {
+		return false
+	}
+	var found []*sitter.Node
+	for i := 0; i < int(body.NamedChildCount()); i++ {
+		member := body.NamedChild(i)
+		if m := s.g.unwrap(member); s.g.methods[m.Type()] && hasBody(m) {
+			found = append(found, member)
+		}
+	}
+	if len(found) == 0 {
+		return false
+	}
+	for _, member := range found {
+		if _, name := s.g.define(s.g.unwrap(member), s.src); name != "" {
+			s.add(KindMethod, container+"."+baseName(name), member)
+		}
+	}
+	return true
+}
+
+// add records the definition n, together with the comments and
+// attributes right above it
+func (s *splitter) add(kind, name string, n *sitter.Node) {
+	c := Chunk{Kind: kind, Name: name, DeclLine: startLine(n), EndLine: endLine(n)}
+	c.StartLine = c.DeclLine
+	for prev := n.PrevNamedSibling(); prev != nil && leading[prev.Type()] && endLine(prev) >= c.StartLine-1; prev = prev.PrevNamedSibling() {
+		if before := prev.PrevNamedSibling(); before != nil && endLine(before) >= startLine(prev) {
+			break // A comment trailing the code before it
+		}
+		c.StartLine = startLine(prev)
+	}
+	c.Text = strings.Join(s.lines[c.StartLine-1:c.EndLine], "\n")
+	s.defs = append(s.defs, c)
+}
+
+// startLine is the 1-based line n starts on
+func startLine(n *sitter.Node) int {
+	return int(n.StartPoint().Row) + 1
+}
+
+// endLine is the 1-based line n ends on. Some nodes, such as line
+// comments, take the newline ending their last line.
+func endLine(n *sitter.Node) int {
+	end := n.EndPoint()
+	if end.Column == 0 && end.Row > n.StartPoint().Row {
+		return int(end.Row)
+	}
+	return int(end.Row) + 1
+}
+
+// hasBody reports whether a function or method is defined rather than only
+// declared, as in an interface
+func hasBody(n *sitter.Node) bool {
+	if n.ChildByFieldName("body") != nil {
+		return true
+	}
+	return childOfType(n, map[string]bool{"function_body": true, "block": true, "compound_statement": true}) != nil
+}
+
+func childOfType(n *sitter.Node, types map[string]bool) *sitter.Node {
+	for i := 0; i < int(n.NamedChildCount()); i++ {
+		if child := n.NamedChild(i); types[child.Type()] {
+			return child
+		}
+	}
+	return nil
+}
+
+// baseName reduces a type as written, such as *Set[K] or fmt::Point<'a>,
+// to its name
+func baseName(s string) string {
+	s = strings.TrimLeft(strings.TrimSpace(s), "*&")
+	if i := strings.IndexAny(s, "<[("); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.LastIndex(s, "::"); i >= 0 {
+		s = s[i+2:]
+	}
+	if i := strings.LastIndex(s, "."); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(s)
+}
+
+// fill returns defs in order with the lines between them as blocks
+func fill(lines []string, defs []Chunk) []Chunk {
+	var chunks []Chunk
+	next := 1
+	addBlocks := func(until int) {
+		for next <= until {
+			end := next + MaxBlockLines - 1
+			if end > until {
+				end = until
+			}
+			text := strings.Join(lines[next-1:end], "\n")
+			if strings.TrimSpace(text) != "" {
+				chunks = append(chunks, Chunk{Kind: KindBlock, StartLine: next, DeclLine: next, EndLine: end, Text: text})
+			}
+			next = end + 1
+		}
+	}
+	for _, d := range defs {
+		if d.StartLine < next {
+			continue
+		}
+		addBlocks(d.StartLine - 1)
+		chunks = append(chunks, d)
+		next = d.EndLine + 1
+	}
+	addBlocks(len(lines))
+	return chunks
+}
@@ -0,0 +1,230 @@
+package codechunk
+
+import (
+	"strings"
+	"testing"
+)
+
+type want struct {
+	kind, name string
+	start, end int
+}
+
+func checkChunks(t *testing.T, chunks []Chunk, wants []want) {
+	t.Helper()
+	if len(chunks) != len(wants) {
+		for _, c := range chunks {
+			t.Logf("%s %q %d-%d", c.Kind, c.Name, c.StartLine, c.EndLine)
+		}
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(wants))
+	}
+	for i, w := range wants {
+		c := chunks[i]
+		if c.Kind != w.kind || c.Name != w.name || c.StartLine != w.start || c.EndLine != w.end {
+			t.Errorf("chunk %d = %s %q %d-%d, want %s %q %d-%d", i, c.Kind, c.Name, c.StartLine, c.EndLine, w.kind, w.name, w.start, w.end)
+		}
+	}
+}
+
+func TestSplitGo(t *testing.T) {
+	src := `// Package shapes has shapes
+package shapes
+
+import "math"
+
+// Circle is round
+type Circle struct {
+	R float64
+}
+
+// Area of the circle
+func (c *Circle) Area() float64 {
+	s := "}" // A brace in a string
+	_ = s
+	return math.Pi * c.R * c.R
+}
+
+func unit() Circle { return Circle{R: 1} }
+`
+	chunks := Split("shapes.go", src)
+	checkChunks(t, chunks, []want{
+		{KindBlock, "", 1, 5},
+		{KindType, "Circle", 6, 9},
+		{KindMethod, "Circle.Area", 11, 16},
+		{KindFunction, "unit", 18, 18},
+	})
+	if chunks[2].DeclLine != 12 || !strings.HasPrefix(chunks[2].Text, "// Area") {
+		t.Errorf("method = %+v", chunks[2])
+	}
+	if found := Find(chunks, "Area"); len(found) != 1 || found[0].Name != "Circle.Area" {
+		t.Errorf("Find(Area) = %+v", found)
+	}
+	if c := At(chunks, 14); c == nil || c.Name != "Circle.Area" {
+		t.Errorf("At(14) = %+v", c)
+	}
+
+	// A snippet without a package clause still parses
+	checkChunks(t, Split("x.go", "func helper() int {\n\treturn 1\n}"), []want{{KindFunction, "helper", 1, 3}})
+	// A definition that does not parse is left to the surrounding block
+	checkChunks(t, Split("x.go", "func ok() {\n}\n\nfunc broken() {\n\tif x {\n}"), []want{
+		{KindFunction, "ok", 1, 2},
+		{KindBlock, "", 3, 6},
+	})
+}
+
+func TestSplitPython(t *testing.T) {
+	src := `import os
+
+@cache
+def load(path):
+    with open(path) as f:
+        return f.read()
+
+class Store:
+    """A store"""
+
+    def __init__(self):
+        self.items = {}
+
+    async def get(self, key):
+        return self.items[key]
+
+class Empty:
+    pass
+
+x = 1
+`
+	checkChunks(t, Split("store.py", src), []want{
+		{KindBlock, "", 1, 2},
+		{KindFunction, "load", 3, 6},
+		{KindBlock, "", 7, 10},
+		{KindMethod, "Store.__init__", 11, 12},
+		{KindMethod, "Store.get", 14, 15},
+		{KindClass, "Empty", 17, 18},
+		{KindBlock, "", 19, 21},
+	})
+}
+
+func TestSplitTypeScript(t *testing.T) {
+	js := `import x from "y";
+
+/** Adds */
+export function add(a, b) {
+  return a + b;
+}
+
+export const mul = (a, b) => {
+  return a * b;
+};
+
+class Calc {
+  run() { return "{"; }
+}
+
+const config = { a: 1 };
+`
+	checkChunks(t, Split("calc.ts", js), []want{
+		{KindBlock, "", 1, 2},
+		{KindFunction, "add", 3, 6},
+		{KindFunction, "mul", 8, 10},
+		{KindBlock, "", 11, 12},
+		{KindMethod, "Calc.run", 13, 13},
+		{KindBlock, "", 14, 17},
+	})
+
+	// A declaration whose braces never close stays in a block
+	checkChunks(t, Split("x.js", "function broken() {\n  return 1;\n"), []want{{KindBlock, "", 1, 3}})
+}
+
+func TestSplitRust(t *testing.T) {
+	rust := `use std::fmt;
+
+pub struct Point<'a> {
+    name: &'a str,
+}
+
+impl<'a> fmt::Display for Point<'a> {
+    fn fmt(&self, f: &mut fmt::Formatter) -> fmt::Result {
+        write!(f, "{}", self.name)
+    }
+}
+
+fn main() {}
+`
+	checkChunks(t, Split("main.rs", rust), []want{
+		{KindBlock, "", 1, 2},
+		{KindType, "Point", 3, 5},
+		{KindBlock, "", 6, 7},
+		{KindMethod, "Point.fmt", 8, 10},
+		{KindBlock, "", 11, 12},
+		{KindFunction, "main", 13, 13},
+	})
+}
+
+func TestSplitJava(t *testing.T) {
+	src := `package shop;
+
+/** A cart */
+public class Cart {
+    private int total;
+
+    public Cart() {
+        total = 0;
+    }
+
+    @Override
+    public String toString() {
+        return "cart";
+    }
+}
+
+interface Priced {
+    int price();
+}
+`
+	checkChunks(t, Split("Cart.java", src), []want{
+		{KindBlock, "", 1, 6},
+		{KindMethod, "Cart.Cart", 7, 9},
+		{KindMethod, "Cart.toString", 11, 14},
+		{KindBlock, "", 15, 16},
+		{KindClass, "Priced", 17, 19},
+	})
+}
+
+func TestSplitCPP(t *testing.T) {
+	src := `#include <string>
+
+namespace geo {
+
+struct Point { int x, y; };
+
+// Area of a shape
+double Shape::area() const {
+    return 0;
+}
+
+template <typename T>
+T twice(T v) { return v * 2; }
+
+}
+`
+	checkChunks(t, Split("geo.cpp", src), []want{
+		{KindBlock, "", 1, 4},
+		{KindType, "Point", 5, 5},
+		{KindMethod, "Shape.area", 7, 10},
+		{KindFunction, "twice", 12, 13},
+		{KindBlock, "", 14, 16},
+	})
+}
+
+func TestSplitOtherFiles(t *testing.T) {
+	lines := make([]string, 130)
+	for i := range lines {
+		lines[i] = "text"
+	}
+	checkChunks(t, Split("README.md", strings.Join(lines, "\n")), []want{
+		{KindBlock, "", 1, 60},
+		{KindBlock, "", 61, 120},
+		{KindBlock, "", 121, 130},
+	})
+}
@@ -0,0 +1,375 @@
+package codechunk
+
+import (
+	"strings"
+
+	sitter "github.com/smacker/go-tree-sitter"
+	"github.com/smacker/go-tree-sitter/c"
+	"github.com/smacker/go-tree-sitter/cpp"
+	"github.com/smacker/go-tree-sitter/csharp"
+	"github.com/smacker/go-tree-sitter/golang"
+	"github.com/smacker/go-tree-sitter/java"
+	"github.com/smacker/go-tree-sitter/javascript"
+	"github.com/smacker/go-tree-sitter/kotlin"
+	"github.com/smacker/go-tree-sitter/php"
+	"github.com/smacker/go-tree-sitter/python"
+	"github.com/smacker/go-tree-sitter/rust"
+	"github.com/smacker/go-tree-sitter/scala"
+	"github.com/smacker/go-tree-sitter/swift"
+	"github.com/smacker/go-tree-sitter/typescript/tsx"
+	"github.com/smacker/go-tree-sitter/typescript/typescript"
+)
+
+// grammar is how the definitions of one language are found in its syntax
+// tree. Node types are tree-sitter's.
+type grammar struct {
+	language    func() *sitter.Language
+	definitions map[string]string // Node type -> kind of definition
+	containers  map[string]bool   // Definitions whose methods are chunked on their own
+	methods     map[string]bool   // Methods in a container's body
+	bodies      map[string]bool   // Container bodies that are not in a "body" field
+	namespaces  map[string]bool   // Nodes whose bodies hold definitions, such as namespaces and modules
+	wrappers    map[string]string // Node type -> field of the definition wrapped by exports, decorators and templates; "" for its first definition
+	// name names the definitions the generic rules cannot, returning ""
+	// for a node that is not a definition after all
+	name func(n *sitter.Node, src []byte) (kind, name string, ok bool)
+}
+
+// leading are comments and attributes kept with the definition below them
+var leading = map[string]bool{
+	"comment":           true,
+	"line_comment":      true,
+	"block_comment":     true,
+	"multiline_comment": true,
+	"attribute_item":    true,
+}
+
+// identifiers are the node types a definition without a "name" field is
+// named by
+var identifiers = map[string]bool{
+	"identifier":        true,
+	"simple_identifier": true,
+	"type_identifier":   true,
+	"name":              true,
+}
+
+func set(types ...string) map[string]bool {
+	m := make(map[string]bool, len(types))
+	for _, t := range types {
+		m[t] = true
+	}
+	return m
+}
+
+// unwrap returns the definition n wraps, or n itself
+func (g *grammar) unwrap(n *sitter.Node) *sitter.Node {
+	for {
+		field, ok := g.wrappers[n.Type()]
+		if !ok {
+			return n
+		}
+		var inner *sitter.Node
+		if field != "" {
+			inner = n.ChildByFieldName(field)
+		} else {
+			for i := 0; i < int(n.NamedChildCount()) && inner == nil; i++ {
+				if child := n.NamedChild(i); g.definitions[child.Type()] != "" || g.methods[child.Type()] {
+					inner = child
+				}
+			}
+		}
+		if inner == nil {
+			return n
+		}
+		n = inner
+	}
+}
+
+// define returns the kind and name of the definition n, or "" when n is
+// not one
+func (g *grammar) define(n *sitter.Node, src []byte) (string, string) {
+	if g.name != nil {
+		if kind, name, ok := g.name(n, src); ok {
+			return kind, name
+		}
+	}
+	kind := g.definitions[n.Type()]
+	if kind == "" && g.methods[n.Type()] {
+		kind = KindFunction
+	}
+	if kind == "" {
+		return "", ""
+	}
+	name := n.ChildByFieldName("name")
+	if name == nil {
+		name = childOfType(n, identifiers)
+	}
+	if name == nil {
+		return "", ""
+	}
+	return kind, baseName(name.Content(src))
+}
+
+// grammars by file extension
+var grammars = map[string]*grammar{}
+
+func register(g *grammar, extensions ...string) {
+	if g.wrappers == nil {
+		g.wrappers = map[string]string{}
+	}
+	for _, ext := range extensions {
+		grammars[ext] = g
+	}
+}
+
+func init() {
+	register(&grammar{
+		language: golang.GetLanguage,
+		definitions: map[string]string{
+			"function_declaration": KindFunction,
+			"method_declaration":   KindMethod,
+			"type_declaration":     KindType,
+		},
+		name: goName,
+	}, ".go")
+
+	register(&grammar{
+		language: python.GetLanguage,
+		definitions: map[string]string{
+			"function_definition": KindFunction,
+			"class_definition":    KindClass,
+		},
+		containers: set("class_definition"),
+		methods:    set("function_definition"),
+		wrappers:   map[string]string{"decorated_definition": "definition"},
+	}, ".py")
+
+	js := func(language func() *sitter.Language) *grammar {
+		return &grammar{
+			language: language,
+			definitions: map[string]string{
+				"function_declaration":           KindFunction,
+				"generator_function_declaration": KindFunction,
+				"class_declaration":              KindClass,
+				"abstract_class_declaration":     KindClass,
+				"interface_declaration":          KindType,
+				"type_alias_declaration":         KindType,
+				"enum_declaration":               KindType,
+			},
+			containers: set("class_declaration", "abstract_class_declaration"),
+			methods:    set("method_definition"),
+			wrappers:   map[string]string{"export_statement": "declaration"},
+			name:       jsName,
+		}
+	}
+	register(js(javascript.GetLanguage), ".js", ".jsx", ".mjs", ".cjs")
+	register(js(typescript.GetLanguage), ".ts", ".mts", ".cts")
+	register(js(tsx.GetLanguage), ".tsx")
+
+	register(&grammar{
+		language: java.GetLanguage,
+		definitions: map[string]string{
+			"class_declaration":     KindClass,
+			"interface_declaration": KindClass,
+			"enum_declaration":      KindClass,
+			"record_declaration":    KindClass,
+		},
+		containers: set("class_declaration", "interface_declaration", "enum_declaration", "record_declaration"),
+		methods:    set("method_declaration", "constructor_declaration", "compact_constructor_declaration"),
+	}, ".java")
+
+	cLike := func(language func() *sitter.Language) *grammar {
+		return &grammar{
+			language: language,
+			definitions: map[string]string{
+				"function_definition": KindFunction,
+				"struct_specifier":    KindType,
+				"union_specifier":     KindType,
+				"enum_specifier":      KindType,
+				"class_specifier":     KindClass,
+				"type_definition":     KindType,
+			},
+			containers: set("class_specifier", "struct_specifier"),
+			methods:    set("function_definition"),
+			namespaces: set("namespace_definition", "linkage_specification"),
+			wrappers:   map[string]string{"template_declaration": ""},
+			name:       cName,
+		}
+	}
+	register(cLike(c.GetLanguage), ".c", ".h")
+	register(cLike(cpp.GetLanguage), ".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx")
+
+	register(&grammar{
+		language: csharp.GetLanguage,
+		definitions: map[string]string{
+			"class_declaration":     KindClass,
+			"struct_declaration":    KindClass,
+			"record_declaration":    KindClass,
+			"interface_declaration": KindClass,
+			"enum_declaration":      KindType,
+		},
+		containers: set("class_declaration", "struct_declaration", "record_declaration", "interface_declaration"),
+		methods:    set("method_declaration", "constructor_declaration"),
+		namespaces: set("namespace_declaration", "file_scoped_namespace_declaration"),
+	}, ".cs")
+
+	register(&grammar{
+		language: rust.GetLanguage,
+		definitions: map[string]string{
+			"function_item": KindFunction,
+			"struct_item":   KindType,
+			"enum_item":     KindType,
+			"union_item":    KindType,
+			"type_item":     KindType,
+			"trait_item":    KindClass,
+			"impl_item":     KindClass,
+		},
+		containers: set("trait_item", "impl_item"),
+		methods:    set("function_item"),
+		namespaces: set("mod_item"),
+		name:       rustName,
+	}, ".rs")
+
+	register(&grammar{
+		language: kotlin.GetLanguage,
+		definitions: map[string]string{
+			"function_declaration": KindFunction,
+			"class_declaration":    KindClass,
+			"object_declaration":   KindClass,
+		},
+		containers: set("class_declaration", "object_declaration"),
+		methods:    set("function_declaration"),
+		bodies:     set("class_body"),
+	}, ".kt", ".kts")
+
+	register(&grammar{
+		language: scala.GetLanguage,
+		definitions: map[string]string{
+			"function_definition": KindFunction,
+			"class_definition":    KindClass,
+			"object_definition":   KindClass,
+			"trait_definition":    KindClass,
+		},
+		containers: set("class_definition", "object_definition", "trait_definition"),
+		methods:    set("function_definition"),
+	}, ".scala")
+
+	register(&grammar{
+		language: swift.GetLanguage,
+		definitions: map[string]string{
+			"function_declaration": KindFunction,
+			"class_declaration":    KindClass,
+			"protocol_declaration": KindClass,
+		},
+		containers: set("class_declaration", "protocol_declaration"),
+		methods:    set("function_declaration"),
+	}, ".swift")
+
+	register(&grammar{
+		language: php.GetLanguage,
+		definitions: map[string]string{
+			"function_definition":   KindFunction,
+			"class_declaration":     KindClass,
+			"interface_declaration": KindClass,
+			"trait_declaration":     KindClass,
+			"enum_declaration":      KindClass,
+		},
+		containers: set("class_declaration", "interface_declaration", "trait_declaration", "enum_declaration"),
+		methods:    set("method_declaration"),
+		namespaces: set("namespace_definition"),
+	}, ".php")
+}
+
+// goName names methods after their receiver's type, and a grouped type
+// declaration after its first type
+func goName(n *sitter.Node, src []byte) (string, string, bool) {
+	switch n.Type() {
+	case "method_declaration":
+		name := n.ChildByFieldName("name")
+		receiver := n.ChildByFieldName("receiver")
+		if name == nil || receiver == nil || receiver.NamedChildCount() == 0 {
+			return "", "", true
+		}
+		recvType := receiver.NamedChild(0).ChildByFieldName("type")
+		if recvType == nil {
+			return "", "", true
+		}
+		return KindMethod, baseName(recvType.Content(src)) + "." + name.Content(src), true
+	case "type_declaration":
+		for i := 0; i < int(n.NamedChildCount()); i++ {
+			if name := n.NamedChild(i).ChildByFieldName("name"); name != nil {
+				return KindType, name.Content(src), true
+			}
+		}
+		return "", "", true
+	}
+	return "", "", false
+}
+
+// jsName names functions assigned to variables, such as
+// const add = (a, b) => a + b
+func jsName(n *sitter.Node, src []byte) (string, string, bool) {
+	if n.Type() != "lexical_declaration" && n.Type() != "variable_declaration" {
+		return "", "", false
+	}
+	declarator := childOfType(n, set("variable_declarator"))
+	if declarator == nil {
+		return "", "", true
+	}
+	name, value := declarator.ChildByFieldName("name"), declarator.ChildByFieldName("value")
+	if name == nil || value == nil {
+		return "", "", true
+	}
+	switch value.Type() {
+	case "arrow_function", "function", "function_expression", "generator_function":
+		return KindFunction, name.Content(src), true
+	}
+	return "", "", true
+}
+
+// cName names C and C++ functions by their declarator, where a qualified
+// name such as Shape::area defines a method, and typedefs by the type they
+// declare. Declarations without a body, such as struct Point;, are not
+// definitions.
+func cName(n *sitter.Node, src []byte) (string, string, bool) {
+	switch n.Type() {
+	case "function_definition":
+		declarator := n.ChildByFieldName("declarator")
+		for declarator != nil && declarator.Type() != "function_declarator" {
+			declarator = declarator.ChildByFieldName("declarator")
+		}
+		if declarator == nil {
+			return "", "", true
+		}
+		name := declarator.ChildByFieldName("declarator")
+		if name == nil {
+			return "", "", true
+		}
+		parts := strings.Split(name.Content(src), "::")
+		if len(parts) > 1 {
+			return KindMethod, baseName(parts[len(parts)-2]) + "." + parts[len(parts)-1], true
+		}
+		return KindFunction, parts[0], true
+	case "type_definition":
+		if declarator := n.ChildByFieldName("declarator"); declarator != nil {
+			return KindType, baseName(declarator.Content(src)), true
+		}
+		return "", "", true
+	case "struct_specifier", "union_specifier", "enum_specifier", "class_specifier":
+		if n.ChildByFieldName("body") == nil {
+			return "", "", true
+		}
+	}
+	return "", "", false
+}
+
+// rustName names impl blocks after the type they implement
+func rustName(n *sitter.Node, src []byte) (string, string, bool) {
+	if n.Type() != "impl_item" {
+		return "", "", false
+	}
+	if t := n.ChildByFieldName("type"); t != nil {
+		return KindClass, baseName(t.Content(src)), true
+	}
+	return "", "", true
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/database"
 	"github.com/jordanhubbard/loom/internal/notifications"
+	"github.com/jordanhubbard/loom/internal/org"
 	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
 )
 
@@ -81,7 +82,7 @@ func (m *Manager) CreateComment(beadID, authorID, authorUsername, content, paren
 	mentions := m.parseMentions(content)
 	comment.Mentions = mentions
 
-	if err := m.processMentions(comment.ID, mentions); err != nil {
+	if err := m.processMentions(comment.ID, authorID, mentions); err != nil {
 		// Log error but don't fail comment creation
 		fmt.Printf("Failed to process mentions: %v\n", err)
 	}
@@ -231,8 +232,9 @@ func (m *Manager) parseMentions(content string) []string {
 	return mentions
 }
 
-// processMentions creates mention records and notifications
-func (m *Manager) processMentions(commentID string, mentions []string) error {
+// processMentions creates mention records and notifications. Only users of
+// the author's organization can be mentioned.
+func (m *Manager) processMentions(commentID, authorID string, mentions []string) error {
 	if len(mentions) == 0 {
 		return nil
 	}
@@ -243,10 +245,19 @@ func (m *Manager) processMentions(commentID string, mentions []string) error {
 		return fmt.Errorf("failed to list users: %w", err)
 	}
 
+	authorOrg := org.DefaultID
+	for _, user := range users {
+		if user.ID == authorID {
+			authorOrg = org.Normalize(user.OrgID)
+		}
+	}
+
 	// Build username to user ID map
 	userMap := make(map[string]string)
 	for _, user := range users {
-		userMap[user.Username] = user.ID
+		if org.Normalize(user.OrgID) == authorOrg {
+			userMap[user.Username] = user.ID
+		}
 	}
 
 	// Create mentions and notifications
@@ -0,0 +1,390 @@
+// Package costestimate predicts what a bead dispatch will cost before it
+// starts. The prediction multiplies the size of the task's prompt by the
+// number of action loop iterations a task of its complexity takes, prices
+// the tokens on the provider the dispatch goes to, and holds the result to
+// per-dispatch and budget thresholds. Once a dispatch finishes, what it
+// actually cost is recorded next to the estimate, and recent dispatches of
+// the same complexity recalibrate the iteration count and token model.
+package costestimate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Estimate outcomes
+const (
+	ActionAllow = "allow"
+	ActionWarn  = "warn"  // Dispatched, but over a warning threshold
+	ActionBlock = "block" // Over the per-dispatch limit; the bead waits for approval
+	ActionHold  = "hold"  // More than the remaining daily budget; the bead waits
+)
+
+// Complexities, as the dispatcher names them
+const (
+	ComplexitySimple   = "simple"
+	ComplexityMedium   = "medium"
+	ComplexityComplex  = "complex"
+	ComplexityExtended = "extended"
+)
+
+// defaultIterations is how many action loop iterations a dispatch of each
+// complexity is expected to take until enough dispatches have finished to
+// calibrate from
+var defaultIterations = map[string]float64{
+	ComplexitySimple:   3,
+	ComplexityMedium:   6,
+	ComplexityComplex:  10,
+	ComplexityExtended: 14,
+}
+
+// charsPerToken converts prompt characters to tokens
+const charsPerToken = 4
+
+// Request describes a dispatch about to start
+type Request struct {
+	BeadID      string
+	ProjectID   string
+	TaskID      string
+	ProviderID  string
+	Model       string // Empty for the provider's configured model
+	Complexity  string
+	PromptChars int // Size of the task's description, context and files
+	// RemainingBudgetUSD is what the project's organization has left of its
+	// daily budget, or negative without a budget
+	RemainingBudgetUSD float64
+}
+
+// Estimate is the prediction for one dispatch and, once it finished, what
+// the dispatch cost
+type Estimate struct {
+	ID               string     `json:"id"`
+	BeadID           string     `json:"bead_id"`
+	ProjectID        string     `json:"project_id,omitempty"`
+	TaskID           string     `json:"task_id,omitempty"`
+	ProviderID       string     `json:"provider_id,omitempty"`
+	Model            string     `json:"model,omitempty"`
+	Complexity       string     `json:"complexity"`
+	PromptTokens     int64      `json:"prompt_tokens"` // Sent on the first iteration
+	Iterations       float64    `json:"iterations"`
+	Tokens           int64      `json:"tokens"`
+	CostUSD          float64    `json:"cost_usd"`
+	Action           string     `json:"action"`
+	Reason           string     `json:"reason,omitempty"`
+	ActualTokens     int64      `json:"actual_tokens,omitempty"`
+	ActualIterations int        `json:"actual_iterations,omitempty"`
+	ActualCostUSD    float64    `json:"actual_cost_usd,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+}
+
+// Filter narrows a listing of estimates
+type Filter struct {
+	BeadID    string
+	ProjectID string
+	Completed bool // Only estimates of finished dispatches
+	Limit     int  // 0 means no limit
+}
+
+// Store persists estimates
+type Store interface {
+	// SaveDispatchEstimate inserts or replaces an estimate
+	SaveDispatchEstimate(e *Estimate) error
+	// GetDispatchEstimateByTask returns the estimate of a dispatch, or nil
+	// if it has none
+	GetDispatchEstimateByTask(taskID string) (*Estimate, error)
+	// ListDispatchEstimates returns estimates, newest first
+	ListDispatchEstimates(f Filter) ([]*Estimate, error)
+}
+
+// Coster prices tokens on a provider's model
+type Coster interface {
+	EstimateCost(providerID, model string, promptTokens, completionTokens int) float64
+}
+
+// Config tunes the token model and the thresholds estimates are held to
+type Config struct {
+	WarnUSD  float64 // Warn above this estimate; 0 never warns
+	BlockUSD float64 // Block above this estimate; 0 never blocks
+	// BudgetWarnShare warns when a dispatch would take more than this share
+	// of the remaining daily budget
+	BudgetWarnShare float64
+
+	SystemTokens     int64 // The persona and action instructions every prompt carries
+	CompletionTokens int64 // Written by the model per iteration
+	GrowthTokens     int64 // Added to the conversation per iteration: the answer and the action results
+
+	MinSamples int // Finished dispatches of a complexity needed to calibrate it
+	Window     int // Most recent finished dispatches calibrated from
+}
+
+// DefaultConfig returns the estimator defaults
+func DefaultConfig() Config {
+	return Config{
+		BudgetWarnShare:  0.5,
+		SystemTokens:     1500,
+		CompletionTokens: 400,
+		GrowthTokens:     1200,
+		MinSamples:       5,
+		Window:           50,
+	}
+}
+
+// Calibration is what finished dispatches of a complexity taught the
+// estimator
+type Calibration struct {
+	Complexity  string  `json:"complexity"`
+	Samples     int     `json:"samples"`
+	Iterations  float64 `json:"iterations"`   // Expected per dispatch
+	TokenFactor float64 `json:"token_factor"` // Actual tokens over the token model's, at the actual iterations
+	Calibrated  bool    `json:"calibrated"`   // Enough samples to override the defaults
+	// MeanErrorPct is the mean absolute error of the cost estimates, in
+	// percent of the actual cost
+	MeanErrorPct float64 `json:"mean_error_pct"`
+	// Bias is the total actual cost over the total estimated cost; above 1
+	// means dispatches cost more than estimated
+	Bias float64 `json:"bias"`
+}
+
+// Estimator makes and calibrates estimates
+type Estimator struct {
+	store  Store
+	coster Coster
+	cfg    Config
+
+	mu          sync.RWMutex
+	calibration map[string]*Calibration
+	recent      map[string][]*Estimate // Finished estimates per complexity, newest first
+}
+
+// New creates an estimator and calibrates it from the finished dispatches
+// in store. Without a store estimates are made but not recorded, so they
+// keep the default calibration.
+func New(store Store, coster Coster, cfg Config) *Estimator {
+	def := DefaultConfig()
+	if cfg.SystemTokens <= 0 {
+		cfg.SystemTokens = def.SystemTokens
+	}
+	if cfg.CompletionTokens <= 0 {
+		cfg.CompletionTokens = def.CompletionTokens
+	}
+	if cfg.GrowthTokens <= 0 {
+		cfg.GrowthTokens = def.GrowthTokens
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = def.MinSamples
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	e := &Estimator{
+		store:       store,
+		coster:      coster,
+		cfg:         cfg,
+		calibration: make(map[string]*Calibration),
+		recent:      make(map[string][]*Estimate),
+	}
+	if store != nil {
+		finished, err := store.ListDispatchEstimates(Filter{Completed: true, Limit: cfg.Window * len(defaultIterations)})
+		if err != nil {
+			log.Printf("[CostEstimate] Failed to load finished dispatches: %v", err)
+		}
+		for _, est := range finished {
+			if list := e.recent[est.Complexity]; len(list) < cfg.Window {
+				e.recent[est.Complexity] = append(list, est)
+			}
+		}
+		for complexity := range e.recent {
+			e.recalibrate(complexity)
+		}
+	}
+	return e
+}
+
+// tokenModel returns the prompt and completion tokens of a dispatch that
+// starts from a prompt of promptTokens and runs for iterations. Every
+// iteration resends the conversation so far, which grows by an answer and
+// its action results each time.
+func (e *Estimator) tokenModel(promptTokens int64, iterations float64) (float64, float64) {
+	n := math.Max(iterations, 1)
+	input := n*float64(promptTokens) + float64(e.cfg.GrowthTokens)*n*(n-1)/2
+	output := n * float64(e.cfg.CompletionTokens)
+	return input, output
+}
+
+// Estimate predicts the cost of a dispatch, decides whether it may go ahead
+// and records the estimate
+func (e *Estimator) Estimate(ctx context.Context, req Request) (*Estimate, error) {
+	if e == nil {
+		return nil, fmt.Errorf("cost estimation is not available")
+	}
+	complexity := req.Complexity
+	if _, ok := defaultIterations[complexity]; !ok {
+		complexity = ComplexityMedium
+	}
+	cal := e.Calibration(complexity)
+
+	prompt := int64(req.PromptChars/charsPerToken) + e.cfg.SystemTokens
+	input, output := e.tokenModel(prompt, cal.Iterations)
+	input *= cal.TokenFactor
+	output *= cal.TokenFactor
+
+	est := &Estimate{
+		ID:           uuid.New().String(),
+		BeadID:       req.BeadID,
+		ProjectID:    req.ProjectID,
+		TaskID:       req.TaskID,
+		ProviderID:   req.ProviderID,
+		Model:        req.Model,
+		Complexity:   complexity,
+		PromptTokens: prompt,
+		Iterations:   math.Round(cal.Iterations*10) / 10,
+		Tokens:       int64(math.Round(input + output)),
+		Action:       ActionAllow,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if e.coster != nil {
+		est.CostUSD = e.coster.EstimateCost(req.ProviderID, req.Model, int(input), int(output))
+	}
+	est.Action, est.Reason = e.check(est.CostUSD, req.RemainingBudgetUSD)
+
+	if e.store != nil {
+		if err := e.store.SaveDispatchEstimate(est); err != nil {
+			return est, err
+		}
+	}
+	return est, nil
+}
+
+// check holds an estimated cost to the thresholds
+func (e *Estimator) check(cost, remaining float64) (string, string) {
+	switch {
+	case e.cfg.BlockUSD > 0 && cost > e.cfg.BlockUSD:
+		return ActionBlock, fmt.Sprintf("estimated $%.4f is over the $%.2f per-dispatch limit", cost, e.cfg.BlockUSD)
+	case remaining >= 0 && cost > remaining:
+		return ActionHold, fmt.Sprintf("estimated $%.4f is more than the $%.4f left of the daily budget", cost, remaining)
+	case e.cfg.WarnUSD > 0 && cost > e.cfg.WarnUSD:
+		return ActionWarn, fmt.Sprintf("estimated $%.4f is over the $%.2f warning threshold", cost, e.cfg.WarnUSD)
+	case remaining >= 0 && e.cfg.BudgetWarnShare > 0 && cost > remaining*e.cfg.BudgetWarnShare:
+		return ActionWarn, fmt.Sprintf("estimated $%.4f would take over %.0f%% of the $%.4f left of the daily budget",
+			cost, e.cfg.BudgetWarnShare*100, remaining)
+	}
+	return ActionAllow, ""
+}
+
+// Complete records what a dispatch actually took and recalibrates its
+// complexity. Dispatches made without an estimate are ignored.
+func (e *Estimator) Complete(ctx context.Context, taskID string, tokens int64, iterations int, costUSD float64) error {
+	if e == nil || e.store == nil || taskID == "" {
+		return nil
+	}
+	est, err := e.store.GetDispatchEstimateByTask(taskID)
+	if err != nil || est == nil {
+		return err
+	}
+	now := time.Now().UTC()
+	est.ActualTokens = tokens
+	est.ActualIterations = iterations
+	est.ActualCostUSD = costUSD
+	est.CompletedAt = &now
+	if err := e.store.SaveDispatchEstimate(est); err != nil {
+		return err
+	}
+
+	e.mu.Lock()
+	list := append([]*Estimate{est}, e.recent[est.Complexity]...)
+	if len(list) > e.cfg.Window {
+		list = list[:e.cfg.Window]
+	}
+	e.recent[est.Complexity] = list
+	e.mu.Unlock()
+	e.recalibrate(est.Complexity)
+	return nil
+}
+
+// recalibrate recomputes a complexity's calibration from its recent
+// finished dispatches
+func (e *Estimator) recalibrate(complexity string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	list := e.recent[complexity]
+	cal := &Calibration{Complexity: complexity, Samples: len(list), Iterations: defaultIterations[complexity], TokenFactor: 1}
+	if len(list) == 0 {
+		delete(e.calibration, complexity)
+		return
+	}
+
+	var iterations, actualTokens, modelTokens, actualCost, estimatedCost, errPct float64
+	priced := 0
+	for _, est := range list {
+		n := float64(est.ActualIterations)
+		if n < 1 {
+			n = 1 // Single-shot dispatches make one request
+		}
+		iterations += n
+		input, output := e.tokenModel(est.PromptTokens, n)
+		modelTokens += input + output
+		actualTokens += float64(est.ActualTokens)
+		if est.ActualCostUSD > 0 {
+			actualCost += est.ActualCostUSD
+			estimatedCost += est.CostUSD
+			errPct += math.Abs(est.CostUSD-est.ActualCostUSD) / est.ActualCostUSD * 100
+			priced++
+		}
+	}
+	if priced > 0 {
+		cal.MeanErrorPct = math.Round(errPct/float64(priced)*10) / 10
+		if estimatedCost > 0 {
+			cal.Bias = math.Round(actualCost/estimatedCost*1000) / 1000
+		}
+	}
+	if len(list) >= e.cfg.MinSamples {
+		cal.Calibrated = true
+		cal.Iterations = iterations / float64(len(list))
+		if modelTokens > 0 && actualTokens > 0 {
+			cal.TokenFactor = math.Min(math.Max(actualTokens/modelTokens, 0.1), 10)
+		}
+	}
+	e.calibration[complexity] = cal
+}
+
+// Calibration returns what the estimator currently assumes for a
+// complexity
+func (e *Estimator) Calibration(complexity string) Calibration {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if cal, ok := e.calibration[complexity]; ok {
+		return *cal
+	}
+	return Calibration{Complexity: complexity, Iterations: defaultIterations[complexity], TokenFactor: 1}
+}
+
+// Calibrations returns the calibration of every complexity, simplest first
+func (e *Estimator) Calibrations() []Calibration {
+	if e == nil {
+		return nil
+	}
+	list := make([]Calibration, 0, len(defaultIterations))
+	for complexity := range defaultIterations {
+		list = append(list, e.Calibration(complexity))
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return defaultIterations[list[i].Complexity] < defaultIterations[list[j].Complexity]
+	})
+	return list
+}
+
+// List returns recorded estimates, newest first
+func (e *Estimator) List(f Filter) ([]*Estimate, error) {
+	if e == nil || e.store == nil {
+		return nil, fmt.Errorf("cost estimates are unavailable without a database")
+	}
+	return e.store.ListDispatchEstimates(f)
+}
@@ -0,0 +1,172 @@
+package costestimate
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+// memStore is a Store kept in a slice, oldest first
+type memStore struct {
+	list []*Estimate
+}
+
+func (m *memStore) SaveDispatchEstimate(e *Estimate) error {
+	for i, existing := range m.list {
+		if existing.ID == e.ID {
+			cp := *e
+			m.list[i] = &cp
+			return nil
+		}
+	}
+	cp := *e
+	m.list = append(m.list, &cp)
+	return nil
+}
+
+func (m *memStore) GetDispatchEstimateByTask(taskID string) (*Estimate, error) {
+	for _, e := range m.list {
+		if e.TaskID == taskID {
+			cp := *e
+			return &cp, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memStore) ListDispatchEstimates(f Filter) ([]*Estimate, error) {
+	var out []*Estimate
+	for i := len(m.list) - 1; i >= 0; i-- {
+		e := m.list[i]
+		if (f.BeadID != "" && e.BeadID != f.BeadID) || (f.Completed && e.CompletedAt == nil) {
+			continue
+		}
+		cp := *e
+		out = append(out, &cp)
+		if f.Limit > 0 && len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// perMillion charges $1 per million prompt tokens and $2 per million
+// completion tokens
+type perMillion struct{}
+
+func (perMillion) EstimateCost(providerID, model string, promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens) + 2*float64(completionTokens)) / 1e6
+}
+
+func TestEstimate_TokenModel(t *testing.T) {
+	e := New(nil, perMillion{}, Config{SystemTokens: 1000, CompletionTokens: 100, GrowthTokens: 500})
+
+	est, err := e.Estimate(context.Background(), Request{BeadID: "b1", Complexity: ComplexitySimple, PromptChars: 4000, RemainingBudgetUSD: -1})
+	if err != nil {
+		t.Fatalf("Estimate failed: %v", err)
+	}
+	// 2000 prompt tokens over 3 iterations, growing by 500 each: 6000 + 1500
+	// in and 300 out
+	if est.PromptTokens != 2000 || est.Iterations != 3 || est.Tokens != 7800 {
+		t.Errorf("expected 2000 prompt tokens, 3 iterations and 7800 tokens, got %d, %v and %d", est.PromptTokens, est.Iterations, est.Tokens)
+	}
+	if math.Abs(est.CostUSD-0.0081) > 1e-9 {
+		t.Errorf("expected $0.0081, got %v", est.CostUSD)
+	}
+	if est.Action != ActionAllow {
+		t.Errorf("expected the dispatch to be allowed, got %s", est.Action)
+	}
+
+	est, _ = e.Estimate(context.Background(), Request{Complexity: "unknown", RemainingBudgetUSD: -1})
+	if est.Complexity != ComplexityMedium {
+		t.Errorf("expected an unknown complexity to be estimated as medium, got %s", est.Complexity)
+	}
+}
+
+func TestEstimate_Thresholds(t *testing.T) {
+	cases := []struct {
+		name      string
+		cfg       Config
+		remaining float64
+		want      string
+	}{
+		{"under everything", Config{WarnUSD: 1, BlockUSD: 2}, -1, ActionAllow},
+		{"over the warning", Config{WarnUSD: 0.001, BlockUSD: 2}, -1, ActionWarn},
+		{"over the limit", Config{WarnUSD: 0.001, BlockUSD: 0.002}, -1, ActionBlock},
+		{"over the remaining budget", Config{}, 0.001, ActionHold},
+		{"most of the remaining budget", Config{BudgetWarnShare: 0.5}, 0.015, ActionWarn},
+		{"a little of the remaining budget", Config{BudgetWarnShare: 0.5}, 100, ActionAllow},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := New(nil, perMillion{}, tc.cfg)
+			// $0.0105 at the default token model
+			est, err := e.Estimate(context.Background(), Request{Complexity: ComplexitySimple, RemainingBudgetUSD: tc.remaining})
+			if err != nil {
+				t.Fatalf("Estimate failed: %v", err)
+			}
+			if est.Action != tc.want {
+				t.Errorf("expected %s for $%.4f, got %s (%s)", tc.want, est.CostUSD, est.Action, est.Reason)
+			}
+			if tc.want != ActionAllow && est.Reason == "" {
+				t.Error("expected a reason")
+			}
+		})
+	}
+}
+
+func TestComplete_Calibrates(t *testing.T) {
+	store := &memStore{}
+	e := New(store, perMillion{}, Config{MinSamples: 3})
+	ctx := context.Background()
+
+	var first *Estimate
+	for i, task := range []string{"t1", "t2", "t3"} {
+		est, err := e.Estimate(ctx, Request{BeadID: "b1", TaskID: task, Complexity: ComplexityComplex, PromptChars: 8000, RemainingBudgetUSD: -1})
+		if err != nil {
+			t.Fatalf("Estimate failed: %v", err)
+		}
+		if i == 0 {
+			first = est
+		}
+		if cal := e.Calibration(ComplexityComplex); cal.Calibrated {
+			t.Fatalf("expected no calibration before %d samples, got %+v", 3, cal)
+		}
+		// Every dispatch takes 4 iterations and twice the modelled tokens
+		in, out := e.tokenModel(est.PromptTokens, 4)
+		if err := e.Complete(ctx, task, int64(2*(in+out)), 4, 2*est.CostUSD); err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+	}
+
+	cal := e.Calibration(ComplexityComplex)
+	if !cal.Calibrated || cal.Samples != 3 || cal.Iterations != 4 {
+		t.Fatalf("expected 3 samples calibrating to 4 iterations, got %+v", cal)
+	}
+	if math.Abs(cal.TokenFactor-2) > 1e-9 {
+		t.Errorf("expected a token factor of 2, got %v", cal.TokenFactor)
+	}
+	if cal.Bias != 2 || cal.MeanErrorPct != 50 {
+		t.Errorf("expected a bias of 2 and a 50%% error, got %v and %v", cal.Bias, cal.MeanErrorPct)
+	}
+
+	next, _ := e.Estimate(ctx, Request{BeadID: "b1", TaskID: "t4", Complexity: ComplexityComplex, PromptChars: 8000, RemainingBudgetUSD: -1})
+	in, out := e.tokenModel(first.PromptTokens, 4)
+	if next.Iterations != 4 || next.Tokens != int64(math.Round(2*(in+out))) {
+		t.Errorf("expected the calibrated estimate to be 4 iterations and %v tokens, got %v and %d", 2*(in+out), next.Iterations, next.Tokens)
+	}
+
+	// A new estimator calibrates from the store
+	reopened := New(store, perMillion{}, Config{MinSamples: 3})
+	if got := reopened.Calibration(ComplexityComplex); got.Samples != 3 || got.Iterations != 4 {
+		t.Errorf("expected the stored dispatches to calibrate a new estimator, got %+v", got)
+	}
+	if got := reopened.Calibration(ComplexitySimple); got.Calibrated || got.Iterations != 3 {
+		t.Errorf("expected other complexities to keep their defaults, got %+v", got)
+	}
+
+	// Dispatches made without an estimate are ignored
+	if err := e.Complete(ctx, "unknown", 100, 1, 0.1); err != nil {
+		t.Errorf("expected an unknown task to be ignored, got %v", err)
+	}
+}
@@ -2,8 +2,12 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
 // Activity represents an activity feed entry
@@ -28,6 +32,8 @@ type Activity struct {
 	AggregationCount int
 	IsAggregated     bool
 	Visibility       string
+	OrgID            string
+	LastEventAt      time.Time // Latest event an aggregated activity stands for; Timestamp when unset
 }
 
 // CreateActivity inserts a new activity
@@ -37,11 +43,16 @@ func (d *Database) CreateActivity(activity *Activity) error {
 			id, event_type, event_id, timestamp, source, actor_id, actor_type,
 			project_id, agent_id, bead_id, provider_id, action, resource_type,
 			resource_id, resource_title, metadata_json, aggregation_key,
-			aggregation_count, is_aggregated, visibility
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			aggregation_count, is_aggregated, visibility, org_id, last_event_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
-	_, err := d.db.Exec(query,
+	lastEventAt := activity.LastEventAt
+	if lastEventAt.IsZero() {
+		lastEventAt = activity.Timestamp
+	}
+
+	_, err := d.exec(query,
 		activity.ID,
 		activity.EventType,
 		sqlNullString(activity.EventID),
@@ -62,6 +73,8 @@ func (d *Database) CreateActivity(activity *Activity) error {
 		activity.AggregationCount,
 		activity.IsAggregated,
 		activity.Visibility,
+		org.Normalize(activity.OrgID),
+		lastEventAt,
 	)
 
 	if err != nil {
@@ -70,23 +83,98 @@ func (d *Database) CreateActivity(activity *Activity) error {
 	return nil
 }
 
-// GetRecentAggregatableActivity finds an aggregatable activity within a time window
+// GetRecentAggregatableActivity finds the aggregated activity with
+// aggregationKey whose latest event is the most recent, if that event
+// happened at or after since
 func (d *Database) GetRecentAggregatableActivity(aggregationKey string, since time.Time) (*Activity, error) {
+	query := `SELECT ` + activityColumns + ` FROM activity_feed
+		WHERE aggregation_key = ? AND COALESCE(last_event_at, timestamp) >= ? AND is_aggregated = TRUE
+		ORDER BY COALESCE(last_event_at, timestamp) DESC
+		LIMIT 1`
+
+	activity, err := scanActivity(d.queryRow(query, aggregationKey, since))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recent aggregatable activity: %w", err)
+	}
+	return activity, nil
+}
+
+// UpdateAggregatedActivity updates an aggregated activity's count and the
+// time of its latest event
+func (d *Database) UpdateAggregatedActivity(activityID string, newCount int, lastEventAt time.Time) error {
 	query := `
-		SELECT id, event_type, event_id, timestamp, source, actor_id, actor_type,
-			   project_id, agent_id, bead_id, provider_id, action, resource_type,
-			   resource_id, resource_title, metadata_json, aggregation_key,
-			   aggregation_count, is_aggregated, visibility
-		FROM activity_feed
-		WHERE aggregation_key = ? AND timestamp >= ? AND is_aggregated = 1
-		ORDER BY timestamp DESC
-		LIMIT 1
+		UPDATE activity_feed
+		SET aggregation_count = ?, is_aggregated = TRUE, last_event_at = ?
+		WHERE id = ?
 	`
 
+	_, err := d.exec(query, newCount, lastEventAt, activityID)
+	if err != nil {
+		return fmt.Errorf("failed to update aggregated activity: %w", err)
+	}
+	return nil
+}
+
+// ListActivities retrieves activities with filters
+func (d *Database) ListActivities(filters ActivityFilters) ([]*Activity, error) {
+	where, args := activityWhere(filters)
+	query := `SELECT ` + activityColumns + ` FROM activity_feed WHERE 1=1` + where
+
+	cols := []string{"timestamp", "id"}
+	if filters.After != nil {
+		after, afterArgs, err := keysetAfter(cols, filters.After, !filters.Ascending)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += orderBy(cols, !filters.Ascending)
+
+	if filters.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filters.Limit)
+	}
+
+	if filters.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, filters.Offset)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activities: %w", err)
+	}
+	defer rows.Close()
+
+	var activities []*Activity
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+
+	return activities, nil
+}
+
+// activityColumns are the columns scanActivity reads, in order
+const activityColumns = `id, event_type, event_id, timestamp, source, actor_id, actor_type,
+	project_id, agent_id, bead_id, provider_id, action, resource_type,
+	resource_id, resource_title, metadata_json, aggregation_key,
+	aggregation_count, is_aggregated, visibility, org_id, last_event_at`
+
+// scanActivity reads a row of activityColumns
+func scanActivity(row interface{ Scan(...interface{}) error }) (*Activity, error) {
 	activity := &Activity{}
 	var eventID, actorID, actorType, projectID, agentID, beadID, providerID, resourceTitle, metadataJSON, aggKey sql.NullString
+	var lastEventAt sql.NullTime
 
-	err := d.db.QueryRow(query, aggregationKey, since).Scan(
+	err := row.Scan(
 		&activity.ID,
 		&activity.EventType,
 		&eventID,
@@ -107,13 +195,11 @@ func (d *Database) GetRecentAggregatableActivity(aggregationKey string, since ti
 		&activity.AggregationCount,
 		&activity.IsAggregated,
 		&activity.Visibility,
+		&activity.OrgID,
+		&lastEventAt,
 	)
-
-	if err == sql.ErrNoRows {
-		return nil, nil
-	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get recent aggregatable activity: %w", err)
+		return nil, fmt.Errorf("failed to scan activity: %w", err)
 	}
 
 	// Convert nullable fields
@@ -127,37 +213,34 @@ func (d *Database) GetRecentAggregatableActivity(aggregationKey string, since ti
 	activity.ResourceTitle = resourceTitle.String
 	activity.MetadataJSON = metadataJSON.String
 	activity.AggregationKey = aggKey.String
-
+	activity.LastEventAt = activity.Timestamp
+	if lastEventAt.Valid {
+		activity.LastEventAt = lastEventAt.Time
+	}
 	return activity, nil
 }
 
-// UpdateAggregatedActivity updates an aggregated activity's count
-func (d *Database) UpdateAggregatedActivity(activityID string, newCount int) error {
-	query := `
-		UPDATE activity_feed
-		SET aggregation_count = ?, is_aggregated = 1
-		WHERE id = ?
-	`
-
-	_, err := d.db.Exec(query, newCount, activityID)
-	if err != nil {
-		return fmt.Errorf("failed to update aggregated activity: %w", err)
+// CountActivities returns how many activities match filters, ignoring
+// Limit, Offset and After
+func (d *Database) CountActivities(filters ActivityFilters) (int, error) {
+	where, args := activityWhere(filters)
+	var count int
+	if err := d.queryRow("SELECT COUNT(*) FROM activity_feed WHERE 1=1"+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count activities: %w", err)
 	}
-	return nil
+	return count, nil
 }
 
-// ListActivities retrieves activities with filters
-func (d *Database) ListActivities(filters ActivityFilters) ([]*Activity, error) {
-	query := `
-		SELECT id, event_type, event_id, timestamp, source, actor_id, actor_type,
-			   project_id, agent_id, bead_id, provider_id, action, resource_type,
-			   resource_id, resource_title, metadata_json, aggregation_key,
-			   aggregation_count, is_aggregated, visibility
-		FROM activity_feed
-		WHERE 1=1
-	`
+// activityWhere builds the conditions of filters, to follow "WHERE 1=1"
+func activityWhere(filters ActivityFilters) (string, []interface{}) {
+	query := ""
 	args := []interface{}{}
 
+	if filters.OrgID != "" {
+		query += " AND org_id = ?"
+		args = append(args, filters.OrgID)
+	}
+
 	if len(filters.ProjectIDs) > 0 {
 		placeholders := ""
 		for i, pid := range filters.ProjectIDs {
@@ -175,11 +258,25 @@ func (d *Database) ListActivities(filters ActivityFilters) ([]*Activity, error)
 		args = append(args, filters.EventType)
 	}
 
+	if len(filters.EventTypes) > 0 {
+		query += " AND event_type IN (" + placeholders(len(filters.EventTypes)) + ")"
+		for _, t := range filters.EventTypes {
+			args = append(args, t)
+		}
+	}
+
 	if filters.ActorID != "" {
 		query += " AND actor_id = ?"
 		args = append(args, filters.ActorID)
 	}
 
+	if len(filters.ActorIDs) > 0 {
+		query += " AND actor_id IN (" + placeholders(len(filters.ActorIDs)) + ")"
+		for _, id := range filters.ActorIDs {
+			args = append(args, id)
+		}
+	}
+
 	if filters.ResourceType != "" {
 		query += " AND resource_type = ?"
 		args = append(args, filters.ResourceType)
@@ -200,85 +297,30 @@ func (d *Database) ListActivities(filters ActivityFilters) ([]*Activity, error)
 		args = append(args, *filters.Aggregated)
 	}
 
-	query += " ORDER BY timestamp DESC"
-
-	if filters.Limit > 0 {
-		query += " LIMIT ?"
-		args = append(args, filters.Limit)
-	}
-
-	if filters.Offset > 0 {
-		query += " OFFSET ?"
-		args = append(args, filters.Offset)
-	}
-
-	rows, err := d.db.Query(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list activities: %w", err)
-	}
-	defer rows.Close()
-
-	var activities []*Activity
-	for rows.Next() {
-		activity := &Activity{}
-		var eventID, actorID, actorType, projectID, agentID, beadID, providerID, resourceTitle, metadataJSON, aggKey sql.NullString
-
-		err := rows.Scan(
-			&activity.ID,
-			&activity.EventType,
-			&eventID,
-			&activity.Timestamp,
-			&activity.Source,
-			&actorID,
-			&actorType,
-			&projectID,
-			&agentID,
-			&beadID,
-			&providerID,
-			&activity.Action,
-			&activity.ResourceType,
-			&activity.ResourceID,
-			&resourceTitle,
-			&metadataJSON,
-			&aggKey,
-			&activity.AggregationCount,
-			&activity.IsAggregated,
-			&activity.Visibility,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan activity: %w", err)
-		}
-
-		// Convert nullable fields
-		activity.EventID = eventID.String
-		activity.ActorID = actorID.String
-		activity.ActorType = actorType.String
-		activity.ProjectID = projectID.String
-		activity.AgentID = agentID.String
-		activity.BeadID = beadID.String
-		activity.ProviderID = providerID.String
-		activity.ResourceTitle = resourceTitle.String
-		activity.MetadataJSON = metadataJSON.String
-		activity.AggregationKey = aggKey.String
-
-		activities = append(activities, activity)
-	}
-
-	return activities, nil
+	return query, args
 }
 
 // ActivityFilters defines filters for querying activities
 type ActivityFilters struct {
+	OrgID        string // Only this organization's activities; empty for all
 	ProjectIDs   []string
 	EventType    string
+	EventTypes   []string // Any of these event types
 	ActorID      string
+	ActorIDs     []string // Any of these actors
 	ResourceType string
 	Since        time.Time
 	Until        time.Time
 	Limit        int
 	Offset       int
+	After        []interface{} // (timestamp, id) of the last activity of the previous page
 	Aggregated   *bool
+	Ascending    bool // oldest first; newest first by default
+}
+
+// placeholders returns n comma-separated "?" placeholders
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
 }
 
 // Notification represents a user notification
@@ -286,6 +328,7 @@ type Notification struct {
 	ID           string
 	UserID       string
 	ActivityID   string
+	ProjectID    string
 	EventType    string
 	Title        string
 	Message      string
@@ -296,21 +339,30 @@ type Notification struct {
 	CreatedAt    time.Time
 	ReadAt       *time.Time
 	ArchivedAt   *time.Time
+	SnoozedUntil *time.Time
 }
 
-// CreateNotification inserts a new notification
+// CreateNotification inserts a new notification, counting it among the
+// user's unread notifications when it is unread
 func (d *Database) CreateNotification(notification *Notification) error {
-	query := `
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := d.rebind(`
 		INSERT INTO notifications (
-			id, user_id, activity_id, event_type, title, message, link,
-			status, priority, metadata_json, created_at, read_at, archived_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`
+			id, user_id, activity_id, project_id, event_type, title, message, link,
+			status, priority, metadata_json, created_at, read_at, archived_at, snoozed_until
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`)
 
-	_, err := d.db.Exec(query,
+	_, err = tx.Exec(query,
 		notification.ID,
 		notification.UserID,
 		sqlNullString(notification.ActivityID),
+		notification.ProjectID,
 		notification.EventType,
 		notification.Title,
 		notification.Message,
@@ -321,57 +373,116 @@ func (d *Database) CreateNotification(notification *Notification) error {
 		notification.CreatedAt,
 		sqlNullTime(notification.ReadAt),
 		sqlNullTime(notification.ArchivedAt),
+		sqlNullTime(notification.SnoozedUntil),
 	)
-
 	if err != nil {
 		return fmt.Errorf("failed to create notification: %w", err)
 	}
-	return nil
+
+	if notification.Status == "unread" {
+		if err := d.addUnread(tx, UnreadCount{
+			UserID:    notification.UserID,
+			ProjectID: notification.ProjectID,
+			Priority:  notification.Priority,
+			Count:     1,
+		}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 // ListNotifications retrieves notifications for a user
 func (d *Database) ListNotifications(userID string, status string, limit, offset int) ([]*Notification, error) {
-	query := `
-		SELECT id, user_id, activity_id, event_type, title, message, link,
-			   status, priority, metadata_json, created_at, read_at, archived_at
-		FROM notifications
-		WHERE user_id = ?
-	`
-	args := []interface{}{userID}
+	return d.QueryNotifications(userID, NotificationFilters{Status: status, Limit: limit, Offset: offset})
+}
 
-	if status != "" {
-		query += " AND status = ?"
-		args = append(args, status)
-	}
+// NotificationFilters defines filters, order and paging for a user's
+// notifications
+type NotificationFilters struct {
+	Status    string
+	Priority  string
+	ProjectID string
+	SortBy    string // "created_at" (default) or "priority"
+	Ascending bool   // oldest or least urgent first; the reverse by default
+	Limit     int
+	Offset    int
+	// After is the keyset of the last notification of the previous page:
+	// (created_at, id), or (priority rank, created_at, id) by priority
+	After []interface{}
+}
+
+// notificationPriorityRank orders notification priorities from least to
+// most urgent
+const notificationPriorityRank = `CASE priority WHEN 'critical' THEN 3 WHEN 'high' THEN 2 WHEN 'normal' THEN 1 ELSE 0 END`
+
+// NotificationPriorityRank is notificationPriorityRank for one priority,
+// for building the keyset of a notification sorted by priority
+func NotificationPriorityRank(priority string) int {
+	switch priority {
+	case "critical":
+		return 3
+	case "high":
+		return 2
+	case "normal":
+		return 1
+	}
+	return 0
+}
 
-	query += " ORDER BY created_at DESC"
+// QueryNotifications retrieves a user's notifications with filters
+func (d *Database) QueryNotifications(userID string, filters NotificationFilters) ([]*Notification, error) {
+	where, args := notificationWhere(userID, filters)
+	query := `SELECT ` + notificationColumns + ` FROM notifications WHERE ` + where
 
-	if limit > 0 {
+	cols := []string{"created_at", "id"}
+	if filters.SortBy == "priority" {
+		cols = []string{notificationPriorityRank, "created_at", "id"}
+	}
+	if filters.After != nil {
+		after, afterArgs, err := keysetAfter(cols, filters.After, !filters.Ascending)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND " + after
+		args = append(args, afterArgs...)
+	}
+	query += orderBy(cols, !filters.Ascending)
+
+	if filters.Limit > 0 {
 		query += " LIMIT ?"
-		args = append(args, limit)
+		args = append(args, filters.Limit)
 	}
 
-	if offset > 0 {
+	if filters.Offset > 0 {
 		query += " OFFSET ?"
-		args = append(args, offset)
+		args = append(args, filters.Offset)
 	}
 
-	rows, err := d.db.Query(query, args...)
+	rows, err := d.query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list notifications: %w", err)
 	}
 	defer rows.Close()
+	return scanNotifications(rows)
+}
 
+// notificationColumns are the columns scanNotifications reads
+const notificationColumns = `id, user_id, activity_id, project_id, event_type, title, message, link,
+	status, priority, metadata_json, created_at, read_at, archived_at, snoozed_until`
+
+func scanNotifications(rows *sql.Rows) ([]*Notification, error) {
 	var notifications []*Notification
 	for rows.Next() {
 		notification := &Notification{}
 		var activityID, link, metadataJSON sql.NullString
-		var readAt, archivedAt sql.NullTime
+		var readAt, archivedAt, snoozedUntil sql.NullTime
 
 		err := rows.Scan(
 			&notification.ID,
 			&notification.UserID,
 			&activityID,
+			&notification.ProjectID,
 			&notification.EventType,
 			&notification.Title,
 			&notification.Message,
@@ -382,6 +493,7 @@ func (d *Database) ListNotifications(userID string, status string, limit, offset
 			&notification.CreatedAt,
 			&readAt,
 			&archivedAt,
+			&snoozedUntil,
 		)
 
 		if err != nil {
@@ -398,84 +510,198 @@ func (d *Database) ListNotifications(userID string, status string, limit, offset
 		if archivedAt.Valid {
 			notification.ArchivedAt = &archivedAt.Time
 		}
+		if snoozedUntil.Valid {
+			notification.SnoozedUntil = &snoozedUntil.Time
+		}
 
 		notifications = append(notifications, notification)
 	}
 
-	return notifications, nil
+	return notifications, rows.Err()
 }
 
-// MarkNotificationRead marks a notification as read
-func (d *Database) MarkNotificationRead(notificationID string) error {
-	query := `
-		UPDATE notifications
-		SET status = 'read', read_at = ?
-		WHERE id = ? AND status = 'unread'
-	`
+// CountNotifications returns how many of a user's notifications match
+// filters, ignoring Limit, Offset and After
+func (d *Database) CountNotifications(userID string, filters NotificationFilters) (int, error) {
+	where, args := notificationWhere(userID, filters)
+	var count int
+	if err := d.queryRow("SELECT COUNT(*) FROM notifications WHERE "+where, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
 
-	_, err := d.db.Exec(query, time.Now(), notificationID)
+func notificationWhere(userID string, filters NotificationFilters) (string, []interface{}) {
+	query := "user_id = ?"
+	args := []interface{}{userID}
+	if filters.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filters.Status)
+	}
+	if filters.Priority != "" {
+		query += " AND priority = ?"
+		args = append(args, filters.Priority)
+	}
+	if filters.ProjectID != "" {
+		query += " AND project_id = ?"
+		args = append(args, filters.ProjectID)
+	}
+	return query, args
+}
+
+// GetNotification returns one of a user's notifications
+func (d *Database) GetNotification(notificationID, userID string) (*Notification, error) {
+	rows, err := d.query(`SELECT `+notificationColumns+` FROM notifications WHERE id = ? AND user_id = ?`, notificationID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to mark notification as read: %w", err)
+		return nil, fmt.Errorf("failed to get notification: %w", err)
 	}
-	return nil
+	defer rows.Close()
+	notifications, err := scanNotifications(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(notifications) == 0 {
+		return nil, fmt.Errorf("notification not found: %s", notificationID)
+	}
+	return notifications[0], nil
 }
 
-// MarkAllNotificationsRead marks all unread notifications as read for a user
-func (d *Database) MarkAllNotificationsRead(userID string) error {
-	query := `
-		UPDATE notifications
-		SET status = 'read', read_at = ?
-		WHERE user_id = ? AND status = 'unread'
-	`
+// MarkNotificationRead marks a notification as read and returns the change
+// to its user's unread counts
+func (d *Database) MarkNotificationRead(notificationID string) ([]UnreadCount, error) {
+	_, deltas, err := d.setNotificationStatus("id = ? AND status = 'unread'", []interface{}{notificationID},
+		"read", "read_at = ?", time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark notification as read: %w", err)
+	}
+	return deltas, nil
+}
 
-	_, err := d.db.Exec(query, time.Now(), userID)
+// MarkAllNotificationsRead marks all unread notifications as read for a
+// user and returns the changes to their unread counts
+func (d *Database) MarkAllNotificationsRead(userID string) ([]UnreadCount, error) {
+	_, deltas, err := d.setNotificationStatus("user_id = ? AND status = 'unread'", []interface{}{userID},
+		"read", "read_at = ?", time.Now())
 	if err != nil {
-		return fmt.Errorf("failed to mark all notifications as read: %w", err)
+		return nil, fmt.Errorf("failed to mark all notifications as read: %w", err)
 	}
-	return nil
+	return deltas, nil
+}
+
+// ArchiveNotification archives one of a user's notifications and returns
+// the change to their unread counts
+func (d *Database) ArchiveNotification(notificationID, userID string) ([]UnreadCount, error) {
+	n, deltas, err := d.setNotificationStatus("id = ? AND user_id = ? AND status <> 'archived'", []interface{}{notificationID, userID},
+		"archived", "archived_at = ?, snoozed_until = NULL", time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to archive notification: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("notification not found or already archived: %s", notificationID)
+	}
+	return deltas, nil
+}
+
+// SnoozeNotification hides one of a user's notifications until until, when
+// WakeSnoozedNotifications makes it unread again, and returns the change to
+// their unread counts. Archived notifications cannot be snoozed.
+func (d *Database) SnoozeNotification(notificationID, userID string, until time.Time) ([]UnreadCount, error) {
+	n, deltas, err := d.setNotificationStatus("id = ? AND user_id = ? AND status IN ('unread', 'read', 'snoozed')", []interface{}{notificationID, userID},
+		"snoozed", "snoozed_until = ?", until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snooze notification: %w", err)
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("notification not found or archived: %s", notificationID)
+	}
+	return deltas, nil
+}
+
+// WakeSnoozedNotifications makes the notifications snoozed until now or
+// earlier unread again and returns them, with the changes to their users'
+// unread counts
+func (d *Database) WakeSnoozedNotifications(now time.Time) ([]*Notification, []UnreadCount, error) {
+	rows, err := d.query(`SELECT `+notificationColumns+` FROM notifications
+		WHERE status = 'snoozed' AND snoozed_until <= ?
+		ORDER BY snoozed_until`, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list snoozed notifications: %w", err)
+	}
+	due, err := scanNotifications(rows)
+	rows.Close()
+	if err != nil || len(due) == 0 {
+		return nil, nil, err
+	}
+
+	args := make([]interface{}, len(due))
+	for i, n := range due {
+		args[i] = n.ID
+		n.Status = "unread"
+		n.ReadAt = nil
+		n.SnoozedUntil = nil
+	}
+	_, deltas, err := d.setNotificationStatus("status = 'snoozed' AND id IN ("+placeholders(len(due))+")", args,
+		"unread", "read_at = NULL, snoozed_until = NULL")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wake snoozed notifications: %w", err)
+	}
+	return due, deltas, nil
 }
 
 // NotificationPreferences represents user notification preferences
 type NotificationPreferences struct {
-	ID                   string
-	UserID               string
-	EnableInApp          bool
-	EnableEmail          bool
-	EnableWebhook        bool
-	SubscribedEventsJSON string
-	DigestMode           string
-	QuietHoursStart      string
-	QuietHoursEnd        string
-	ProjectFiltersJSON   string
-	MinPriority          string
-	UpdatedAt            time.Time
+	ID                      string
+	UserID                  string
+	EnableInApp             bool
+	EnableEmail             bool
+	EnableWebhook           bool
+	EnablePush              bool
+	SubscribedEventsJSON    string
+	DigestMode              string
+	QuietHoursStart         string
+	QuietHoursEnd           string
+	QuietScheduleJSON       string
+	Timezone                string
+	QuietHoursAllowCritical bool
+	ProjectFiltersJSON      string
+	MinPriority             string
+	ChannelsJSON            string
+	MuteRulesJSON           string
+	UpdatedAt               time.Time
 }
 
 // GetNotificationPreferences retrieves notification preferences for a user
 func (d *Database) GetNotificationPreferences(userID string) (*NotificationPreferences, error) {
 	query := `
 		SELECT id, user_id, enable_in_app, enable_email, enable_webhook,
-			   subscribed_events_json, digest_mode, quiet_hours_start,
-			   quiet_hours_end, project_filters_json, min_priority, updated_at
+			   enable_push, subscribed_events_json, digest_mode, quiet_hours_start,
+			   quiet_hours_end, quiet_schedule_json, timezone, quiet_hours_allow_critical,
+			   project_filters_json, min_priority, channels_json, mute_rules_json, updated_at
 		FROM notification_preferences
 		WHERE user_id = ?
 	`
 
 	prefs := &NotificationPreferences{}
-	var subscribedEvents, quietStart, quietEnd, projectFilters sql.NullString
+	var subscribedEvents, quietStart, quietEnd, quietSchedule, projectFilters, channels, muteRules sql.NullString
 
-	err := d.db.QueryRow(query, userID).Scan(
+	err := d.queryRow(query, userID).Scan(
 		&prefs.ID,
 		&prefs.UserID,
 		&prefs.EnableInApp,
 		&prefs.EnableEmail,
 		&prefs.EnableWebhook,
+		&prefs.EnablePush,
 		&subscribedEvents,
 		&prefs.DigestMode,
 		&quietStart,
 		&quietEnd,
+		&quietSchedule,
+		&prefs.Timezone,
+		&prefs.QuietHoursAllowCritical,
 		&projectFilters,
 		&prefs.MinPriority,
+		&channels,
+		&muteRules,
 		&prefs.UpdatedAt,
 	)
 
@@ -489,7 +715,10 @@ func (d *Database) GetNotificationPreferences(userID string) (*NotificationPrefe
 	prefs.SubscribedEventsJSON = subscribedEvents.String
 	prefs.QuietHoursStart = quietStart.String
 	prefs.QuietHoursEnd = quietEnd.String
+	prefs.QuietScheduleJSON = quietSchedule.String
 	prefs.ProjectFiltersJSON = projectFilters.String
+	prefs.ChannelsJSON = channels.String
+	prefs.MuteRulesJSON = muteRules.String
 
 	return prefs, nil
 }
@@ -499,34 +728,47 @@ func (d *Database) UpsertNotificationPreferences(prefs *NotificationPreferences)
 	query := `
 		INSERT INTO notification_preferences (
 			id, user_id, enable_in_app, enable_email, enable_webhook,
-			subscribed_events_json, digest_mode, quiet_hours_start,
-			quiet_hours_end, project_filters_json, min_priority, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			enable_push, subscribed_events_json, digest_mode, quiet_hours_start,
+			quiet_hours_end, quiet_schedule_json, timezone, quiet_hours_allow_critical,
+			project_filters_json, min_priority, channels_json, mute_rules_json, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(user_id) DO UPDATE SET
 			enable_in_app = excluded.enable_in_app,
 			enable_email = excluded.enable_email,
 			enable_webhook = excluded.enable_webhook,
+			enable_push = excluded.enable_push,
 			subscribed_events_json = excluded.subscribed_events_json,
 			digest_mode = excluded.digest_mode,
 			quiet_hours_start = excluded.quiet_hours_start,
 			quiet_hours_end = excluded.quiet_hours_end,
+			quiet_schedule_json = excluded.quiet_schedule_json,
+			timezone = excluded.timezone,
+			quiet_hours_allow_critical = excluded.quiet_hours_allow_critical,
 			project_filters_json = excluded.project_filters_json,
 			min_priority = excluded.min_priority,
+			channels_json = excluded.channels_json,
+			mute_rules_json = excluded.mute_rules_json,
 			updated_at = excluded.updated_at
 	`
 
-	_, err := d.db.Exec(query,
+	_, err := d.exec(query,
 		prefs.ID,
 		prefs.UserID,
 		prefs.EnableInApp,
 		prefs.EnableEmail,
 		prefs.EnableWebhook,
+		prefs.EnablePush,
 		sqlNullString(prefs.SubscribedEventsJSON),
 		prefs.DigestMode,
 		sqlNullString(prefs.QuietHoursStart),
 		sqlNullString(prefs.QuietHoursEnd),
+		sqlNullString(prefs.QuietScheduleJSON),
+		prefs.Timezone,
+		prefs.QuietHoursAllowCritical,
 		sqlNullString(prefs.ProjectFiltersJSON),
 		prefs.MinPriority,
+		sqlNullString(prefs.ChannelsJSON),
+		sqlNullString(prefs.MuteRulesJSON),
 		prefs.UpdatedAt,
 	)
 
@@ -540,11 +782,11 @@ func (d *Database) UpsertNotificationPreferences(prefs *NotificationPreferences)
 func (d *Database) CreateUser(id, username, email, role string) error {
 	query := `
 		INSERT INTO users (id, username, email, role, is_active, created_at, updated_at)
-		VALUES (?, ?, ?, ?, 1, ?, ?)
+		VALUES (?, ?, ?, ?, TRUE, ?, ?)
 	`
 
 	now := time.Now()
-	_, err := d.db.Exec(query, id, username, email, role, now, now)
+	_, err := d.exec(query, id, username, email, role, now, now)
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
 	}
@@ -556,10 +798,11 @@ func (d *Database) ListUsers() ([]struct {
 	Username string
 	Email    string
 	Role     string
+	OrgID    string
 }, error) {
-	query := `SELECT id, username, email, role FROM users WHERE is_active = 1`
+	query := `SELECT id, username, email, role, org_id FROM users WHERE is_active = TRUE`
 
-	rows, err := d.db.Query(query)
+	rows, err := d.query(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list users: %w", err)
 	}
@@ -570,6 +813,7 @@ func (d *Database) ListUsers() ([]struct {
 		Username string
 		Email    string
 		Role     string
+		OrgID    string
 	}
 
 	for rows.Next() {
@@ -578,9 +822,10 @@ func (d *Database) ListUsers() ([]struct {
 			Username string
 			Email    string
 			Role     string
+			OrgID    string
 		}
 		var email sql.NullString
-		err := rows.Scan(&u.ID, &u.Username, &email, &u.Role)
+		err := rows.Scan(&u.ID, &u.Username, &email, &u.Role, &u.OrgID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
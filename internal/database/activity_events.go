@@ -0,0 +1,132 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ActivityEvent is one of the events an aggregated activity stands for
+type ActivityEvent struct {
+	ID            string
+	ActivityID    string
+	EventID       string
+	Timestamp     time.Time
+	ActorID       string
+	AgentID       string
+	BeadID        string
+	ResourceID    string
+	ResourceTitle string
+	MetadataJSON  string
+}
+
+// CreateActivityEvent records an event of an aggregated activity
+func (d *Database) CreateActivityEvent(e *ActivityEvent) error {
+	query := `
+		INSERT INTO activity_events (
+			id, activity_id, event_id, timestamp, actor_id, agent_id, bead_id,
+			resource_id, resource_title, metadata_json
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := d.exec(query,
+		e.ID,
+		e.ActivityID,
+		sqlNullString(e.EventID),
+		e.Timestamp,
+		sqlNullString(e.ActorID),
+		sqlNullString(e.AgentID),
+		sqlNullString(e.BeadID),
+		e.ResourceID,
+		sqlNullString(e.ResourceTitle),
+		sqlNullString(e.MetadataJSON),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create activity event: %w", err)
+	}
+	return nil
+}
+
+// ActivityEventFilters selects the events of an aggregated activity
+type ActivityEventFilters struct {
+	ActivityID string
+	Limit      int
+	Offset     int
+	After      []interface{} // (timestamp, id) of the last event of the previous page
+	Descending bool          // newest first; oldest first by default
+}
+
+// ListActivityEvents returns the events of an aggregated activity
+func (d *Database) ListActivityEvents(f ActivityEventFilters) ([]*ActivityEvent, error) {
+	query := `
+		SELECT id, activity_id, event_id, timestamp, actor_id, agent_id, bead_id,
+			resource_id, resource_title, metadata_json
+		FROM activity_events
+		WHERE activity_id = ?`
+	args := []interface{}{f.ActivityID}
+
+	cols := []string{"timestamp", "id"}
+	if f.After != nil {
+		cond, afterArgs, err := keysetAfter(cols, f.After, f.Descending)
+		if err != nil {
+			return nil, err
+		}
+		query += " AND " + cond
+		args = append(args, afterArgs...)
+	}
+	query += orderBy(cols, f.Descending)
+	if f.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, f.Limit)
+	}
+	if f.Offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, f.Offset)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*ActivityEvent
+	for rows.Next() {
+		e := &ActivityEvent{}
+		var eventID, actorID, agentID, beadID, resourceTitle, metadataJSON sql.NullString
+		if err := rows.Scan(&e.ID, &e.ActivityID, &eventID, &e.Timestamp, &actorID, &agentID, &beadID,
+			&e.ResourceID, &resourceTitle, &metadataJSON); err != nil {
+			return nil, fmt.Errorf("failed to scan activity event: %w", err)
+		}
+		e.EventID = eventID.String
+		e.ActorID = actorID.String
+		e.AgentID = agentID.String
+		e.BeadID = beadID.String
+		e.ResourceTitle = resourceTitle.String
+		e.MetadataJSON = metadataJSON.String
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// CountActivityEvents returns how many events of an aggregated activity
+// are kept
+func (d *Database) CountActivityEvents(activityID string) (int, error) {
+	var n int
+	if err := d.queryRow(`SELECT COUNT(*) FROM activity_events WHERE activity_id = ?`, activityID).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count activity events: %w", err)
+	}
+	return n, nil
+}
+
+// GetActivity returns an activity by ID, or nil when there is none
+func (d *Database) GetActivity(id string) (*Activity, error) {
+	activity, err := scanActivity(d.queryRow(`SELECT `+activityColumns+` FROM activity_feed WHERE id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get activity: %w", err)
+	}
+	return activity, nil
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// ActivityRollup is a summary row that expired activity feed events are
+// compacted into
+type ActivityRollup struct {
+	Key            string    `json:"key"`
+	OrgID          string    `json:"org_id"`
+	EventType      string    `json:"event_type"`
+	ProjectID      string    `json:"project_id,omitempty"`
+	ActorID        string    `json:"actor_id,omitempty"`
+	ResourceType   string    `json:"resource_type"`
+	AggregationKey string    `json:"aggregation_key,omitempty"`
+	PeriodStart    time.Time `json:"period_start"` // The hour of an aggregation key, else the day
+	EventCount     int       `json:"event_count"`
+	FirstAt        time.Time `json:"first_at"`
+	LastAt         time.Time `json:"last_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// ActivityRollupFilters defines filters for querying activity rollups
+type ActivityRollupFilters struct {
+	OrgID     string // Only this organization's rollups; empty for all
+	ProjectID string
+	EventType string
+	Since     time.Time // Periods starting at or after
+	Until     time.Time // Periods starting at or before
+	Limit     int
+}
+
+// ListExpiredActivities returns up to limit activities older than before,
+// oldest first. Activities that unread or snoozed notifications point at
+// are kept until they are read, since deleting them would delete the
+// notifications.
+func (d *Database) ListExpiredActivities(before time.Time, limit int) ([]*Activity, error) {
+	query := `SELECT ` + activityColumns + ` FROM activity_feed
+		WHERE timestamp < ?
+		AND id NOT IN (SELECT activity_id FROM notifications WHERE status IN ('unread', 'snoozed') AND activity_id IS NOT NULL)
+		ORDER BY timestamp ASC`
+	args := []interface{}{before}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired activities: %w", err)
+	}
+	defer rows.Close()
+
+	var activities []*Activity
+	for rows.Next() {
+		activity, err := scanActivity(rows)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, activity)
+	}
+	return activities, rows.Err()
+}
+
+// CompactActivities adds rollups to the stored summary rows and deletes the
+// activities they summarize, in one transaction
+func (d *Database) CompactActivities(rollups []*ActivityRollup, activityIDs []string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsert := d.rebind(`
+		INSERT INTO activity_rollups (
+			rollup_key, org_id, event_type, project_id, actor_id, resource_type,
+			aggregation_key, period_start, event_count, first_at, last_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(rollup_key) DO UPDATE SET
+			event_count = activity_rollups.event_count + excluded.event_count,
+			first_at = CASE WHEN excluded.first_at < activity_rollups.first_at THEN excluded.first_at ELSE activity_rollups.first_at END,
+			last_at = CASE WHEN excluded.last_at > activity_rollups.last_at THEN excluded.last_at ELSE activity_rollups.last_at END,
+			updated_at = excluded.updated_at
+	`)
+	for _, r := range rollups {
+		if _, err := tx.Exec(upsert,
+			r.Key,
+			org.Normalize(r.OrgID),
+			r.EventType,
+			sqlNullString(r.ProjectID),
+			sqlNullString(r.ActorID),
+			r.ResourceType,
+			sqlNullString(r.AggregationKey),
+			r.PeriodStart,
+			r.EventCount,
+			r.FirstAt,
+			r.LastAt,
+			r.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("failed to save activity rollup: %w", err)
+		}
+	}
+
+	del := d.rebind(`DELETE FROM activity_feed WHERE id = ?`)
+	for _, id := range activityIDs {
+		if _, err := tx.Exec(del, id); err != nil {
+			return fmt.Errorf("failed to delete activity: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteActivityRollups removes the rollups whose last event is older than
+// before and returns how many there were
+func (d *Database) DeleteActivityRollups(before time.Time) (int64, error) {
+	res, err := d.exec(`DELETE FROM activity_rollups WHERE last_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete activity rollups: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// ListActivityRollups retrieves rollups with filters, newest period first
+func (d *Database) ListActivityRollups(filters ActivityRollupFilters) ([]*ActivityRollup, error) {
+	query := `
+		SELECT rollup_key, org_id, event_type, project_id, actor_id, resource_type,
+			   aggregation_key, period_start, event_count, first_at, last_at, updated_at
+		FROM activity_rollups
+		WHERE 1=1`
+	args := []interface{}{}
+	if filters.OrgID != "" {
+		query += " AND org_id = ?"
+		args = append(args, filters.OrgID)
+	}
+	if filters.ProjectID != "" {
+		query += " AND project_id = ?"
+		args = append(args, filters.ProjectID)
+	}
+	if filters.EventType != "" {
+		query += " AND event_type = ?"
+		args = append(args, filters.EventType)
+	}
+	if !filters.Since.IsZero() {
+		query += " AND period_start >= ?"
+		args = append(args, filters.Since)
+	}
+	if !filters.Until.IsZero() {
+		query += " AND period_start <= ?"
+		args = append(args, filters.Until)
+	}
+	query += " ORDER BY period_start DESC, event_type"
+	if filters.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, filters.Limit)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity rollups: %w", err)
+	}
+	defer rows.Close()
+
+	var rollups []*ActivityRollup
+	for rows.Next() {
+		r := &ActivityRollup{}
+		var projectID, actorID, aggKey sql.NullString
+		if err := rows.Scan(&r.Key, &r.OrgID, &r.EventType, &projectID, &actorID, &r.ResourceType,
+			&aggKey, &r.PeriodStart, &r.EventCount, &r.FirstAt, &r.LastAt, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan activity rollup: %w", err)
+		}
+		r.ProjectID = projectID.String
+		r.ActorID = actorID.String
+		r.AggregationKey = aggKey.String
+		rollups = append(rollups, r)
+	}
+	return rollups, rows.Err()
+}
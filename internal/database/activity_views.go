@@ -0,0 +1,175 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ActivityView is a saved set of activity feed filters
+type ActivityView struct {
+	ID          string
+	OrgID       string
+	OwnerID     string
+	Name        string
+	Shared      bool
+	FiltersJSON string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// ActivityViewSubscription subscribes a user to the activities a view
+// matches
+type ActivityViewSubscription struct {
+	ViewID      string
+	UserID      string
+	MinPriority string
+	CreatedAt   time.Time
+	View        *ActivityView // The subscribed view, when listed
+}
+
+const activityViewColumns = `v.id, v.org_id, v.owner_id, v.name, v.shared, v.filters_json, v.created_at, v.updated_at`
+
+func scanActivityView(row interface{ Scan(...interface{}) error }, extra ...interface{}) (*ActivityView, error) {
+	v := &ActivityView{}
+	var filtersJSON sql.NullString
+	dest := append([]interface{}{&v.ID, &v.OrgID, &v.OwnerID, &v.Name, &v.Shared, &filtersJSON, &v.CreatedAt, &v.UpdatedAt}, extra...)
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	v.FiltersJSON = filtersJSON.String
+	return v, nil
+}
+
+// CreateActivityView saves a new activity view
+func (d *Database) CreateActivityView(v *ActivityView) error {
+	query := `
+		INSERT INTO activity_views (id, org_id, owner_id, name, shared, filters_json, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	if _, err := d.exec(query, v.ID, v.OrgID, v.OwnerID, v.Name, v.Shared, sqlNullString(v.FiltersJSON), v.CreatedAt, v.UpdatedAt); err != nil {
+		return fmt.Errorf("failed to create activity view: %w", err)
+	}
+	return nil
+}
+
+// UpdateActivityView saves the name, sharing and filters of an activity view
+func (d *Database) UpdateActivityView(v *ActivityView) error {
+	query := `UPDATE activity_views SET name = ?, shared = ?, filters_json = ?, updated_at = ? WHERE id = ?`
+	if _, err := d.exec(query, v.Name, v.Shared, sqlNullString(v.FiltersJSON), v.UpdatedAt, v.ID); err != nil {
+		return fmt.Errorf("failed to update activity view: %w", err)
+	}
+	return nil
+}
+
+// GetActivityView returns an activity view, or nil when there is none
+func (d *Database) GetActivityView(id string) (*ActivityView, error) {
+	v, err := scanActivityView(d.queryRow(`SELECT `+activityViewColumns+` FROM activity_views v WHERE v.id = ?`, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get activity view: %w", err)
+	}
+	return v, nil
+}
+
+// ListActivityViews returns the views of an organization a user may use:
+// their own and those shared with the organization, by name
+func (d *Database) ListActivityViews(orgID, userID string) ([]*ActivityView, error) {
+	rows, err := d.query(`
+		SELECT `+activityViewColumns+`
+		FROM activity_views v
+		WHERE v.org_id = ? AND (v.owner_id = ? OR v.shared = ?)
+		ORDER BY v.name, v.id`, orgID, userID, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity views: %w", err)
+	}
+	defer rows.Close()
+
+	var views []*ActivityView
+	for rows.Next() {
+		v, err := scanActivityView(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity view: %w", err)
+		}
+		views = append(views, v)
+	}
+	return views, rows.Err()
+}
+
+// DeleteActivityView deletes an activity view and its subscriptions
+func (d *Database) DeleteActivityView(id string) error {
+	if _, err := d.exec(`DELETE FROM activity_views WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete activity view: %w", err)
+	}
+	return nil
+}
+
+// SaveActivityViewSubscription subscribes a user to a view, or changes the
+// priority their subscription notifies from
+func (d *Database) SaveActivityViewSubscription(s *ActivityViewSubscription) error {
+	query := `
+		INSERT INTO activity_view_subscriptions (view_id, user_id, min_priority, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (view_id, user_id) DO UPDATE SET min_priority = excluded.min_priority
+	`
+	if _, err := d.exec(query, s.ViewID, s.UserID, s.MinPriority, s.CreatedAt); err != nil {
+		return fmt.Errorf("failed to save activity view subscription: %w", err)
+	}
+	return nil
+}
+
+// DeleteActivityViewSubscription unsubscribes a user from a view
+func (d *Database) DeleteActivityViewSubscription(viewID, userID string) error {
+	if _, err := d.exec(`DELETE FROM activity_view_subscriptions WHERE view_id = ? AND user_id = ?`, viewID, userID); err != nil {
+		return fmt.Errorf("failed to delete activity view subscription: %w", err)
+	}
+	return nil
+}
+
+// ActivityViewSubscriptionFilters selects view subscriptions
+type ActivityViewSubscriptionFilters struct {
+	OrgID  string // Of views of this organization; empty for all
+	UserID string // Of this user; empty for all
+}
+
+// ListActivityViewSubscriptions returns view subscriptions with the views
+// they subscribe to. Subscriptions to views no longer shared with their
+// subscriber are left out.
+func (d *Database) ListActivityViewSubscriptions(f ActivityViewSubscriptionFilters) ([]*ActivityViewSubscription, error) {
+	query := `
+		SELECT ` + activityViewColumns + `, s.user_id, s.min_priority, s.created_at
+		FROM activity_view_subscriptions s
+		JOIN activity_views v ON v.id = s.view_id
+		WHERE (v.owner_id = s.user_id OR v.shared = ?)`
+	args := []interface{}{true}
+	if f.OrgID != "" {
+		query += " AND v.org_id = ?"
+		args = append(args, f.OrgID)
+	}
+	if f.UserID != "" {
+		query += " AND s.user_id = ?"
+		args = append(args, f.UserID)
+	}
+	query += " ORDER BY v.id, s.user_id"
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list activity view subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*ActivityViewSubscription
+	for rows.Next() {
+		s := &ActivityViewSubscription{}
+		v, err := scanActivityView(rows, &s.UserID, &s.MinPriority, &s.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan activity view subscription: %w", err)
+		}
+		s.ViewID, s.View = v.ID, v
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
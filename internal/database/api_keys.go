@@ -0,0 +1,117 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+const apiKeyColumns = `id, name, user_id, key_prefix, key_hash, scopes, permissions, is_active,
+	expires_at, created_at, last_used, revoked_at, replaced_by, org_id`
+
+// UpsertAPIKey inserts or updates an API key
+func (d *Database) UpsertAPIKey(k *auth.APIKey) error {
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key scopes: %w", err)
+	}
+	permissions, err := json.Marshal(k.Permissions)
+	if err != nil {
+		return fmt.Errorf("failed to encode API key permissions: %w", err)
+	}
+
+	query := `
+		INSERT INTO api_keys (` + apiKeyColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			is_active = excluded.is_active,
+			expires_at = excluded.expires_at,
+			last_used = excluded.last_used,
+			revoked_at = excluded.revoked_at,
+			replaced_by = excluded.replaced_by
+	`
+
+	_, err = d.db.Exec(query,
+		k.ID,
+		k.Name,
+		k.UserID,
+		k.KeyPrefix,
+		k.KeyHash,
+		string(scopes),
+		string(permissions),
+		k.IsActive,
+		sqlNullTime(nonZeroTime(k.ExpiresAt)),
+		k.CreatedAt,
+		sqlNullTime(nonZeroTime(k.LastUsed)),
+		sqlNullTime(k.RevokedAt),
+		sqlNullString(k.ReplacedBy),
+		org.Normalize(k.OrgID),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert API key: %w", err)
+	}
+	return nil
+}
+
+// ListAPIKeys returns all API keys, oldest first
+func (d *Database) ListAPIKeys() ([]*auth.APIKey, error) {
+	rows, err := d.db.Query(`SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*auth.APIKey
+	for rows.Next() {
+		k := &auth.APIKey{}
+		var scopes, replacedBy sql.NullString
+		var permissions string
+		var expiresAt, lastUsed, revokedAt sql.NullTime
+
+		if err := rows.Scan(
+			&k.ID,
+			&k.Name,
+			&k.UserID,
+			&k.KeyPrefix,
+			&k.KeyHash,
+			&scopes,
+			&permissions,
+			&k.IsActive,
+			&expiresAt,
+			&k.CreatedAt,
+			&lastUsed,
+			&revokedAt,
+			&replacedBy,
+			&k.OrgID,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan API key: %w", err)
+		}
+
+		if scopes.Valid && scopes.String != "" {
+			if err := json.Unmarshal([]byte(scopes.String), &k.Scopes); err != nil {
+				return nil, fmt.Errorf("failed to decode API key scopes: %w", err)
+			}
+		}
+		if err := json.Unmarshal([]byte(permissions), &k.Permissions); err != nil {
+			return nil, fmt.Errorf("failed to decode API key permissions: %w", err)
+		}
+		k.ExpiresAt = expiresAt.Time
+		k.LastUsed = lastUsed.Time
+		k.RevokedAt = nullTimePtr(revokedAt)
+		k.ReplacedBy = replacedBy.String
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+func nonZeroTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,89 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/artifacts"
+)
+
+const runArtifactColumns = `id, dispatch_id, bead_id, project_id, kind, name, iteration, digest, size, created_at`
+
+// PutArtifactBlob stores content under its digest. A digest that is already
+// stored is left untouched, which is what dedupes identical reruns.
+func (d *Database) PutArtifactBlob(digest string, content []byte) error {
+	query := `INSERT OR IGNORE INTO artifact_blobs (digest, size, content, created_at) VALUES (?, ?, ?, ?)`
+	if _, err := d.db.Exec(query, digest, len(content), content, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to store artifact blob: %w", err)
+	}
+	return nil
+}
+
+// GetArtifactBlob returns the content stored under digest, or nil if unknown
+func (d *Database) GetArtifactBlob(digest string) ([]byte, error) {
+	var content []byte
+	err := d.db.QueryRow(`SELECT content FROM artifact_blobs WHERE digest = ?`, digest).Scan(&content)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get artifact blob: %w", err)
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	return content, nil
+}
+
+// AddRunArtifact records an artifact of a dispatch, assigning its ID
+func (d *Database) AddRunArtifact(a *artifacts.Artifact) error {
+	if a.CreatedAt.IsZero() {
+		a.CreatedAt = time.Now().UTC()
+	}
+	query := `INSERT INTO run_artifacts (dispatch_id, bead_id, project_id, kind, name, iteration, digest, size, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := d.db.Exec(query,
+		a.DispatchID,
+		sqlNullString(a.BeadID),
+		sqlNullString(a.ProjectID),
+		a.Kind,
+		sqlNullString(a.Name),
+		a.Iteration,
+		a.Digest,
+		a.Size,
+		a.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert run artifact: %w", err)
+	}
+	if id, err := res.LastInsertId(); err == nil {
+		a.ID = id
+	}
+	return nil
+}
+
+// ListRunArtifacts returns a dispatch's artifacts in the order they were recorded
+func (d *Database) ListRunArtifacts(dispatchID string) ([]*artifacts.Artifact, error) {
+	query := `SELECT ` + runArtifactColumns + ` FROM run_artifacts WHERE dispatch_id = ? ORDER BY id`
+	rows, err := d.db.Query(query, dispatchID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list run artifacts: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*artifacts.Artifact
+	for rows.Next() {
+		var a artifacts.Artifact
+		var beadID, projectID, name sql.NullString
+		if err := rows.Scan(&a.ID, &a.DispatchID, &beadID, &projectID, &a.Kind, &name,
+			&a.Iteration, &a.Digest, &a.Size, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan run artifact: %w", err)
+		}
+		a.BeadID = beadID.String
+		a.ProjectID = projectID.String
+		a.Name = name.String
+		list = append(list, &a)
+	}
+	return list, rows.Err()
+}
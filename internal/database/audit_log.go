@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+)
+
+const auditLogColumns = `seq, timestamp, actor, action, resource, project_id, outcome, details, prev_hash, hash`
+
+// AppendAuditEntry inserts an audit entry. Sequence numbers are assigned by
+// the audit logger, so a duplicate sequence fails rather than forking the chain.
+func (d *Database) AppendAuditEntry(e *audit.Entry) error {
+	var details sql.NullString
+	if len(e.Details) > 0 {
+		data, err := json.Marshal(e.Details)
+		if err != nil {
+			return fmt.Errorf("failed to encode audit details: %w", err)
+		}
+		details = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := `INSERT INTO audit_log (` + auditLogColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := d.db.Exec(query,
+		e.Sequence,
+		e.Timestamp.UTC().Format(audit.TimestampLayout),
+		sqlNullString(e.Actor),
+		e.Action,
+		sqlNullString(e.Resource),
+		sqlNullString(e.ProjectID),
+		e.Outcome,
+		details,
+		e.PrevHash,
+		e.Hash,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	return nil
+}
+
+// LastAuditEntry returns the newest audit entry, or nil if the log is empty
+func (d *Database) LastAuditEntry() (*audit.Entry, error) {
+	query := `SELECT ` + auditLogColumns + ` FROM audit_log ORDER BY seq DESC LIMIT 1`
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last audit entry: %w", err)
+	}
+	defer rows.Close()
+
+	entries, err := scanAuditEntries(rows)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	return entries[0], nil
+}
+
+// QueryAuditEntries returns audit entries matching the filter in sequence order
+func (d *Database) QueryAuditEntries(f audit.Filter) ([]*audit.Entry, error) {
+	var conds []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		conds = append(conds, cond)
+		args = append(args, arg)
+	}
+	if f.Actor != "" {
+		add("actor = ?", f.Actor)
+	}
+	if f.Action != "" {
+		if strings.HasSuffix(f.Action, ".*") {
+			add("action LIKE ?", strings.TrimSuffix(f.Action, "*")+"%")
+		} else {
+			add("action = ?", f.Action)
+		}
+	}
+	if f.Resource != "" {
+		add("resource = ?", f.Resource)
+	}
+	if f.ProjectID != "" {
+		add("project_id = ?", f.ProjectID)
+	}
+	if f.Outcome != "" {
+		add("outcome = ?", f.Outcome)
+	}
+	if !f.Since.IsZero() {
+		add("timestamp >= ?", f.Since.UTC().Format(audit.TimestampLayout))
+	}
+	if !f.Until.IsZero() {
+		add("timestamp < ?", f.Until.UTC().Format(audit.TimestampLayout))
+	}
+	if f.AfterSeq > 0 {
+		add("seq > ?", f.AfterSeq)
+	}
+
+	query := `SELECT ` + auditLogColumns + ` FROM audit_log`
+	if len(conds) > 0 {
+		query += ` WHERE ` + strings.Join(conds, " AND ")
+	}
+	query += ` ORDER BY seq ASC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+	return scanAuditEntries(rows)
+}
+
+func scanAuditEntries(rows *sql.Rows) ([]*audit.Entry, error) {
+	var entries []*audit.Entry
+	for rows.Next() {
+		var e audit.Entry
+		var ts string
+		var actor, resource, projectID, details sql.NullString
+		if err := rows.Scan(&e.Sequence, &ts, &actor, &e.Action, &resource, &projectID,
+			&e.Outcome, &details, &e.PrevHash, &e.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		parsed, err := time.Parse(audit.TimestampLayout, ts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit timestamp %q: %w", ts, err)
+		}
+		e.Timestamp = parsed
+		e.Actor = actor.String
+		e.Resource = resource.String
+		e.ProjectID = projectID.String
+		if details.Valid && details.String != "" {
+			if err := json.Unmarshal([]byte(details.String), &e.Details); err != nil {
+				return nil, fmt.Errorf("failed to decode audit details: %w", err)
+			}
+		}
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
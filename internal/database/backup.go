@@ -0,0 +1,146 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// SchemaVersion returns the newest schema migration applied to the database
+func (d *Database) SchemaVersion(ctx context.Context) (int64, error) {
+	m, err := d.Migrator()
+	if err != nil {
+		return 0, err
+	}
+	version, _, err := m.Version(ctx)
+	return version, err
+}
+
+// LatestSchemaVersion returns the newest schema migration this binary knows
+func (d *Database) LatestSchemaVersion() (int64, error) {
+	m, err := d.Migrator()
+	if err != nil {
+		return 0, err
+	}
+	return m.Latest(), nil
+}
+
+// Dump writes a consistent copy of the database to path while it stays in
+// use: SQLite through its online backup API, Postgres with pg_dump in its
+// custom format.
+func (d *Database) Dump(ctx context.Context, path string) error {
+	if d.dbType == "postgres" {
+		return runPgTool(ctx, "pg_dump", "--format=custom", "--file", path, "--dbname", d.dsn)
+	}
+	return d.copySQLite(ctx, path, false)
+}
+
+// VerifyDump checks a file written by Dump and returns its schema version.
+// SQLite copies are integrity checked and read; a Postgres archive is only
+// listed, since its contents cannot be queried without restoring it, so the
+// version returned is -1.
+func (d *Database) VerifyDump(ctx context.Context, path string) (int64, error) {
+	if d.dbType == "postgres" {
+		if err := runPgTool(ctx, "pg_restore", "--list", path); err != nil {
+			return 0, err
+		}
+		return -1, nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRowContext(ctx, "PRAGMA integrity_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("integrity check: %w", err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("integrity check: %s", result)
+	}
+
+	var version sql.NullInt64
+	var dirty int
+	err = db.QueryRowContext(ctx, "SELECT MAX(version), COALESCE(SUM(CASE WHEN dirty THEN 1 ELSE 0 END), 0) FROM schema_migrations").Scan(&version, &dirty)
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	if dirty > 0 {
+		return 0, fmt.Errorf("schema migrations are dirty")
+	}
+	return version.Int64, nil
+}
+
+// Restore replaces the contents of the database with a file written by Dump.
+// Nothing else should be using the database while it runs.
+func (d *Database) Restore(ctx context.Context, path string) error {
+	if d.dbType == "postgres" {
+		return runPgTool(ctx, "pg_restore", "--clean", "--if-exists", "--no-owner", "--single-transaction", "--dbname", d.dsn, path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	return d.copySQLite(ctx, path, true)
+}
+
+// copySQLite copies the live database to the file at path, or the file into
+// the live database when restore is set, one page at a time with the SQLite
+// online backup API
+func (d *Database) copySQLite(ctx context.Context, path string, restore bool) error {
+	file, err := (&sqlite3.SQLiteDriver{}).Open(path)
+	if err != nil {
+		return fmt.Errorf("open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	conn, err := d.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn interface{}) error {
+		live, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("not a SQLite connection")
+		}
+		dest, src := file.(*sqlite3.SQLiteConn), live
+		if restore {
+			dest, src = live, file.(*sqlite3.SQLiteConn)
+		}
+		b, err := dest.Backup("main", src, "main")
+		if err != nil {
+			return err
+		}
+		if _, err := b.Step(-1); err != nil {
+			b.Finish()
+			return err
+		}
+		return b.Finish()
+	})
+}
+
+// runPgTool runs one of the Postgres client tools, which must be on PATH
+func runPgTool(ctx context.Context, name string, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %w: %s", name, err, msg)
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/benchmark"
+)
+
+const benchmarkRunColumns = `id, trigger, created_by, tasks, passed, failed, providers_json, results_json, started_at, finished_at`
+
+// SaveBenchmarkRun stores a provider benchmark run
+func (d *Database) SaveBenchmarkRun(r *benchmark.Run) error {
+	providers, err := json.Marshal(r.Providers)
+	if err != nil {
+		return fmt.Errorf("failed to encode benchmark summaries: %w", err)
+	}
+	results, err := json.Marshal(r.Results)
+	if err != nil {
+		return fmt.Errorf("failed to encode benchmark results: %w", err)
+	}
+	_, err = d.exec(`INSERT INTO benchmark_runs (`+benchmarkRunColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.Trigger, r.CreatedBy, r.Tasks, r.Passed, r.Failed, string(providers), string(results), r.StartedAt, r.FinishedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save benchmark run: %w", err)
+	}
+	return nil
+}
+
+// GetBenchmarkRun returns a benchmark run, or nil if it does not exist
+func (d *Database) GetBenchmarkRun(id string) (*benchmark.Run, error) {
+	r, err := scanBenchmarkRun(d.queryRow(`SELECT `+benchmarkRunColumns+` FROM benchmark_runs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListBenchmarkRuns returns the most recent benchmark runs, newest first;
+// limit 0 means no limit
+func (d *Database) ListBenchmarkRuns(limit int) ([]*benchmark.Run, error) {
+	query := `SELECT ` + benchmarkRunColumns + ` FROM benchmark_runs ORDER BY started_at DESC`
+	var args []interface{}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list benchmark runs: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*benchmark.Run
+	for rows.Next() {
+		r, err := scanBenchmarkRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func scanBenchmarkRun(row interface{ Scan(...interface{}) error }) (*benchmark.Run, error) {
+	r := &benchmark.Run{}
+	var providers, results string
+	if err := row.Scan(&r.ID, &r.Trigger, &r.CreatedBy, &r.Tasks, &r.Passed, &r.Failed, &providers, &results,
+		&r.StartedAt, &r.FinishedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan benchmark run: %w", err)
+	}
+	if err := json.Unmarshal([]byte(providers), &r.Providers); err != nil {
+		return nil, fmt.Errorf("failed to decode benchmark summaries: %w", err)
+	}
+	if err := json.Unmarshal([]byte(results), &r.Results); err != nil {
+		return nil, fmt.Errorf("failed to decode benchmark results: %w", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DispatchCheckpoint captures the state of an in-flight action loop for a
+// bead so that it can be resumed after a restart
+type DispatchCheckpoint struct {
+	BeadID        string
+	ProjectID     string
+	AgentID       string
+	TaskID        string
+	Iteration     int
+	TokensUsed    int
+	MessagesJSON  string
+	ActionLogJSON string
+	ResumeCount   int
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+// SaveDispatchCheckpoint inserts or updates the checkpoint for a bead
+func (d *Database) SaveDispatchCheckpoint(cp *DispatchCheckpoint) error {
+	now := time.Now()
+	if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = now
+	}
+	cp.UpdatedAt = now
+
+	query := `
+		INSERT INTO dispatch_checkpoints (
+			bead_id, project_id, agent_id, task_id, iteration, tokens_used,
+			messages_json, action_log_json, resume_count, created_at, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(bead_id) DO UPDATE SET
+			project_id = excluded.project_id,
+			agent_id = excluded.agent_id,
+			task_id = excluded.task_id,
+			iteration = excluded.iteration,
+			tokens_used = excluded.tokens_used,
+			messages_json = excluded.messages_json,
+			action_log_json = excluded.action_log_json,
+			resume_count = excluded.resume_count,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := d.db.Exec(query,
+		cp.BeadID,
+		cp.ProjectID,
+		sqlNullString(cp.AgentID),
+		sqlNullString(cp.TaskID),
+		cp.Iteration,
+		cp.TokensUsed,
+		cp.MessagesJSON,
+		cp.ActionLogJSON,
+		cp.ResumeCount,
+		cp.CreatedAt,
+		cp.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save dispatch checkpoint: %w", err)
+	}
+	return nil
+}
+
+// GetDispatchCheckpoint retrieves the checkpoint for a bead. It returns
+// nil, nil when the bead has no checkpoint.
+func (d *Database) GetDispatchCheckpoint(beadID string) (*DispatchCheckpoint, error) {
+	query := `
+		SELECT bead_id, project_id, agent_id, task_id, iteration, tokens_used,
+			   messages_json, action_log_json, resume_count, created_at, updated_at
+		FROM dispatch_checkpoints
+		WHERE bead_id = ?
+	`
+
+	cp := &DispatchCheckpoint{}
+	var agentID, taskID sql.NullString
+
+	err := d.db.QueryRow(query, beadID).Scan(
+		&cp.BeadID,
+		&cp.ProjectID,
+		&agentID,
+		&taskID,
+		&cp.Iteration,
+		&cp.TokensUsed,
+		&cp.MessagesJSON,
+		&cp.ActionLogJSON,
+		&cp.ResumeCount,
+		&cp.CreatedAt,
+		&cp.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispatch checkpoint: %w", err)
+	}
+
+	cp.AgentID = agentID.String
+	cp.TaskID = taskID.String
+	return cp, nil
+}
+
+// ListDispatchCheckpoints returns all checkpoints, oldest first
+func (d *Database) ListDispatchCheckpoints() ([]*DispatchCheckpoint, error) {
+	query := `
+		SELECT bead_id, project_id, agent_id, task_id, iteration, tokens_used,
+			   resume_count, created_at, updated_at
+		FROM dispatch_checkpoints
+		ORDER BY updated_at ASC
+	`
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispatch checkpoints: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []*DispatchCheckpoint
+	for rows.Next() {
+		cp := &DispatchCheckpoint{}
+		var agentID, taskID sql.NullString
+		if err := rows.Scan(
+			&cp.BeadID,
+			&cp.ProjectID,
+			&agentID,
+			&taskID,
+			&cp.Iteration,
+			&cp.TokensUsed,
+			&cp.ResumeCount,
+			&cp.CreatedAt,
+			&cp.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan dispatch checkpoint: %w", err)
+		}
+		cp.AgentID = agentID.String
+		cp.TaskID = taskID.String
+		checkpoints = append(checkpoints, cp)
+	}
+
+	return checkpoints, rows.Err()
+}
+
+// DeleteDispatchCheckpoint removes the checkpoint for a bead
+func (d *Database) DeleteDispatchCheckpoint(beadID string) error {
+	if _, err := d.db.Exec(`DELETE FROM dispatch_checkpoints WHERE bead_id = ?`, beadID); err != nil {
+		return fmt.Errorf("failed to delete dispatch checkpoint: %w", err)
+	}
+	return nil
+}
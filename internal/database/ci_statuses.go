@@ -0,0 +1,93 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/cistatus"
+)
+
+const ciStatusColumns = `project_id, branch, bead_id, sha, state, checks_json, failures_json, updated_at`
+
+// SaveCIStatus inserts or replaces the CI status of a branch
+func (d *Database) SaveCIStatus(s *cistatus.Status) error {
+	checks, err := json.Marshal(s.Checks)
+	if err != nil {
+		return fmt.Errorf("failed to encode checks: %w", err)
+	}
+	failures, err := json.Marshal(s.Failures)
+	if err != nil {
+		return fmt.Errorf("failed to encode failures: %w", err)
+	}
+	_, err = d.db.Exec(`INSERT OR REPLACE INTO ci_statuses (`+ciStatusColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.ProjectID,
+		s.Branch,
+		sqlNullString(s.BeadID),
+		sqlNullString(s.SHA),
+		s.State,
+		string(checks),
+		string(failures),
+		s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save CI status: %w", err)
+	}
+	return nil
+}
+
+// GetCIStatus returns the CI status of a branch, or nil if none was saved
+func (d *Database) GetCIStatus(projectID, branch string) (*cistatus.Status, error) {
+	s, err := scanCIStatus(d.db.QueryRow(`SELECT `+ciStatusColumns+` FROM ci_statuses WHERE project_id = ? AND branch = ?`, projectID, branch))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// ListCIStatuses returns the CI statuses of a project's branches, or of
+// every project's when projectID is empty, most recently updated first
+func (d *Database) ListCIStatuses(projectID string) ([]*cistatus.Status, error) {
+	query := `SELECT ` + ciStatusColumns + ` FROM ci_statuses`
+	var args []interface{}
+	if projectID != "" {
+		query += ` WHERE project_id = ?`
+		args = append(args, projectID)
+	}
+	rows, err := d.db.Query(query+` ORDER BY updated_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CI statuses: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*cistatus.Status
+	for rows.Next() {
+		s, err := scanCIStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+func scanCIStatus(row interface{ Scan(...interface{}) error }) (*cistatus.Status, error) {
+	s := &cistatus.Status{}
+	var checks, failures string
+	var beadID, sha sql.NullString
+	if err := row.Scan(&s.ProjectID, &s.Branch, &beadID, &sha, &s.State, &checks, &failures, &s.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan CI status: %w", err)
+	}
+	if err := json.Unmarshal([]byte(checks), &s.Checks); err != nil {
+		return nil, fmt.Errorf("failed to decode checks: %w", err)
+	}
+	if err := json.Unmarshal([]byte(failures), &s.Failures); err != nil {
+		return nil, fmt.Errorf("failed to decode failures: %w", err)
+	}
+	s.BeadID = beadID.String
+	s.SHA = sha.String
+	return s, nil
+}
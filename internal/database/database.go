@@ -1,13 +1,16 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/org"
 	"github.com/jordanhubbard/loom/pkg/models"
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -17,10 +20,29 @@ type Database struct {
 	db         *sql.DB
 	dbType     string // "sqlite" or "postgres"
 	supportsHA bool   // true if database supports HA features
+	dsn        string // Postgres connection string, for pg_dump and pg_restore
+
+	searchMu     sync.Mutex
+	lessonSearch LessonSearchConfig
+	lessonScans  chan struct{} // Bounds concurrent lesson searches
 }
 
-// New creates a new database instance and initializes the schema
+// New opens a SQLite database and applies any pending schema migrations
 func New(dbPath string) (*Database, error) {
+	d, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Migrate(context.Background()); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return d, nil
+}
+
+// Open opens a SQLite database without touching its schema, so migrations
+// can be inspected and repaired
+func Open(dbPath string) (*Database, error) {
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
@@ -38,70 +60,11 @@ func New(dbPath string) (*Database, error) {
 		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
 	}
 
-	d := &Database{
+	return &Database{
 		db:         db,
 		dbType:     "sqlite",
 		supportsHA: false,
-	}
-
-	// Initialize schema
-	if err := d.initSchema(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	// Run migrations
-	if err := d.migrateProviderOwnership(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate provider ownership: %w", err)
-	}
-
-	if err := d.migrateProviderRouting(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate provider routing: %w", err)
-	}
-
-	if err := d.migrateMotivations(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate motivations: %w", err)
-	}
-
-	if err := d.migrateWorkflows(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate workflows: %w", err)
-	}
-
-	if err := d.migrateActivity(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate activity: %w", err)
-	}
-
-	if err := d.migrateComments(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate comments: %w", err)
-	}
-
-	if err := d.migrateConversations(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate conversations: %w", err)
-	}
-
-	if err := migratePatterns(db); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate patterns: %w", err)
-	}
-
-	if err := d.migrateCredentials(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate credentials: %w", err)
-	}
-
-	if err := d.migrateLessons(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate lessons: %w", err)
-	}
-
-	return d, nil
+	}, nil
 }
 
 // Close closes the database connection
@@ -124,201 +87,6 @@ func (d *Database) SupportsHA() bool {
 	return d.supportsHA
 }
 
-// initSchema creates the database tables
-func (d *Database) initSchema() error {
-	schema := `
-	-- Global configuration key-value store
-	CREATE TABLE IF NOT EXISTS config_kv (
-		key TEXT PRIMARY KEY,
-		value TEXT NOT NULL,
-		updated_at DATETIME NOT NULL
-	);
-
-	-- Global providers (shared across all projects)
-	CREATE TABLE IF NOT EXISTS providers (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		type TEXT NOT NULL,
-		endpoint TEXT NOT NULL,
-		model TEXT,
-		configured_model TEXT,
-		selected_model TEXT,
-		selection_reason TEXT,
-		model_score REAL,
-		selected_gpu TEXT,
-		gpu_constraints_json TEXT,
-		description TEXT,
-		requires_key BOOLEAN NOT NULL DEFAULT 0,
-		key_id TEXT,
-		owner_id TEXT,
-		is_shared BOOLEAN NOT NULL DEFAULT 1,
-		status TEXT NOT NULL DEFAULT 'active',
-		last_heartbeat_at DATETIME,
-		last_heartbeat_latency_ms INTEGER,
-		last_heartbeat_error TEXT,
-		metrics_json TEXT,
-		schema_version TEXT NOT NULL DEFAULT '1.0',
-		attributes_json TEXT,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL
-	);
-
-	-- Projects with hierarchy support (parent_id for sub-projects)
-	CREATE TABLE IF NOT EXISTS projects (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		git_repo TEXT NOT NULL,
-		branch TEXT NOT NULL,
-		beads_path TEXT NOT NULL,
-		parent_id TEXT,
-		is_perpetual BOOLEAN NOT NULL DEFAULT 0,
-		is_sticky BOOLEAN NOT NULL DEFAULT 0,
-		git_strategy TEXT NOT NULL DEFAULT 'direct',
-		status TEXT NOT NULL DEFAULT 'open',
-		context_json TEXT,
-		schema_version TEXT NOT NULL DEFAULT '1.0',
-		attributes_json TEXT,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL,
-		closed_at DATETIME,
-		FOREIGN KEY (parent_id) REFERENCES projects(id) ON DELETE SET NULL
-	);
-
-	-- Org charts define the team structure for each project
-	CREATE TABLE IF NOT EXISTS org_charts (
-		id TEXT PRIMARY KEY,
-		project_id TEXT NOT NULL,
-		name TEXT NOT NULL,
-		is_template BOOLEAN NOT NULL DEFAULT 0,
-		parent_id TEXT,
-		schema_version TEXT NOT NULL DEFAULT '1.0',
-		attributes_json TEXT,
-		created_at DATETIME NOT NULL,
-		updated_at DATETIME NOT NULL,
-		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
-		FOREIGN KEY (parent_id) REFERENCES org_charts(id) ON DELETE SET NULL
-	);
-
-	-- Positions within an org chart (role slots)
-	CREATE TABLE IF NOT EXISTS org_chart_positions (
-		id TEXT PRIMARY KEY,
-		org_chart_id TEXT NOT NULL,
-		role_name TEXT NOT NULL,
-		persona_path TEXT NOT NULL,
-		required BOOLEAN NOT NULL DEFAULT 0,
-		max_instances INTEGER NOT NULL DEFAULT 0,
-		reports_to TEXT,
-		schema_version TEXT NOT NULL DEFAULT '1.0',
-		attributes_json TEXT,
-		created_at DATETIME NOT NULL,
-		FOREIGN KEY (org_chart_id) REFERENCES org_charts(id) ON DELETE CASCADE,
-		FOREIGN KEY (reports_to) REFERENCES org_chart_positions(id) ON DELETE SET NULL
-	);
-
-	-- Agent instances assigned to positions
-	CREATE TABLE IF NOT EXISTS agents (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		role TEXT,
-		persona_name TEXT,
-		provider_id TEXT,
-		status TEXT NOT NULL DEFAULT 'idle',
-		current_bead TEXT,
-		project_id TEXT,
-		position_id TEXT,
-		schema_version TEXT NOT NULL DEFAULT '1.0',
-		attributes_json TEXT,
-		started_at DATETIME NOT NULL,
-		last_active DATETIME NOT NULL,
-		FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL,
-		FOREIGN KEY (position_id) REFERENCES org_chart_positions(id) ON DELETE SET NULL,
-		FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE SET NULL
-	);
-
-	-- Indexes for performance
-	CREATE INDEX IF NOT EXISTS idx_agents_status ON agents(status);
-	CREATE INDEX IF NOT EXISTS idx_agents_project_id ON agents(project_id);
-	CREATE INDEX IF NOT EXISTS idx_agents_position_id ON agents(position_id);
-	CREATE INDEX IF NOT EXISTS idx_providers_status ON providers(status);
-	CREATE INDEX IF NOT EXISTS idx_projects_parent_id ON projects(parent_id);
-	CREATE INDEX IF NOT EXISTS idx_org_charts_project_id ON org_charts(project_id);
-	CREATE INDEX IF NOT EXISTS idx_positions_org_chart_id ON org_chart_positions(org_chart_id);
-
-	-- Command Logs for agent shell command execution
-	CREATE TABLE IF NOT EXISTS command_logs (
-		id TEXT PRIMARY KEY,
-		agent_id TEXT NOT NULL,
-		bead_id TEXT,
-		project_id TEXT,
-		command TEXT NOT NULL,
-		working_dir TEXT NOT NULL,
-		exit_code INTEGER NOT NULL,
-		stdout TEXT,
-		stderr TEXT,
-		duration_ms INTEGER NOT NULL,
-		started_at DATETIME NOT NULL,
-		completed_at DATETIME NOT NULL,
-		context TEXT,
-		created_at DATETIME NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_command_logs_agent_id ON command_logs(agent_id);
-	CREATE INDEX IF NOT EXISTS idx_command_logs_bead_id ON command_logs(bead_id);
-	CREATE INDEX IF NOT EXISTS idx_command_logs_project_id ON command_logs(project_id);
-	CREATE INDEX IF NOT EXISTS idx_command_logs_created_at ON command_logs(created_at);
-	`
-
-	if _, err := d.db.Exec(schema); err != nil {
-		return fmt.Errorf("failed to create schema: %w", err)
-	}
-
-	// Best-effort migrations for existing databases.
-	// SQLite doesn't support IF NOT EXISTS on ADD COLUMN.
-
-	// Provider migrations
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN model TEXT")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN configured_model TEXT")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN selected_model TEXT")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN selection_reason TEXT")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN model_score REAL")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN selected_gpu TEXT")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN last_heartbeat_at DATETIME")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN last_heartbeat_latency_ms INTEGER")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN last_heartbeat_error TEXT")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN schema_version TEXT DEFAULT '1.0'")
-	_, _ = d.db.Exec("ALTER TABLE providers ADD COLUMN attributes_json TEXT")
-	_, _ = d.db.Exec("UPDATE providers SET schema_version = '1.0' WHERE schema_version IS NULL")
-
-	// Project migrations
-	_, _ = d.db.Exec("ALTER TABLE projects ADD COLUMN is_sticky BOOLEAN")
-	_, _ = d.db.Exec("UPDATE projects SET is_sticky = 0 WHERE is_sticky IS NULL")
-	_, _ = d.db.Exec("ALTER TABLE projects ADD COLUMN parent_id TEXT")
-	_, _ = d.db.Exec("ALTER TABLE projects ADD COLUMN closed_at DATETIME")
-	_, _ = d.db.Exec("ALTER TABLE projects ADD COLUMN schema_version TEXT DEFAULT '1.0'")
-	_, _ = d.db.Exec("ALTER TABLE projects ADD COLUMN attributes_json TEXT")
-	_, _ = d.db.Exec("UPDATE projects SET schema_version = '1.0' WHERE schema_version IS NULL")
-	_, _ = d.db.Exec("ALTER TABLE projects ADD COLUMN git_strategy TEXT NOT NULL DEFAULT 'direct'")
-
-	// Agent migrations
-	_, _ = d.db.Exec("ALTER TABLE agents ADD COLUMN provider_id TEXT")
-	_, _ = d.db.Exec("ALTER TABLE agents ADD COLUMN role TEXT")
-	_, _ = d.db.Exec("ALTER TABLE agents ADD COLUMN position_id TEXT")
-	_, _ = d.db.Exec("ALTER TABLE agents ADD COLUMN schema_version TEXT DEFAULT '1.0'")
-	_, _ = d.db.Exec("ALTER TABLE agents ADD COLUMN attributes_json TEXT")
-	_, _ = d.db.Exec("UPDATE agents SET schema_version = '1.0' WHERE schema_version IS NULL")
-
-	// Org chart migrations
-	_, _ = d.db.Exec("ALTER TABLE org_charts ADD COLUMN schema_version TEXT DEFAULT '1.0'")
-	_, _ = d.db.Exec("ALTER TABLE org_charts ADD COLUMN attributes_json TEXT")
-	_, _ = d.db.Exec("UPDATE org_charts SET schema_version = '1.0' WHERE schema_version IS NULL")
-
-	// Position migrations
-	_, _ = d.db.Exec("ALTER TABLE org_chart_positions ADD COLUMN schema_version TEXT DEFAULT '1.0'")
-	_, _ = d.db.Exec("ALTER TABLE org_chart_positions ADD COLUMN attributes_json TEXT")
-	_, _ = d.db.Exec("UPDATE org_chart_positions SET schema_version = '1.0' WHERE schema_version IS NULL")
-
-	return nil
-}
-
 // Configuration KV
 
 func (d *Database) SetConfigValue(key string, value string) error {
@@ -363,6 +131,15 @@ func (d *Database) UpsertProject(project *models.Project) error {
 		contextJSON = string(b)
 	}
 
+	complianceJSON := ""
+	if project.Compliance.Enabled() {
+		b, err := json.Marshal(project.Compliance)
+		if err != nil {
+			return fmt.Errorf("failed to marshal project compliance: %w", err)
+		}
+		complianceJSON = string(b)
+	}
+
 	if project.CreatedAt.IsZero() {
 		project.CreatedAt = time.Now()
 	}
@@ -374,9 +151,10 @@ func (d *Database) UpsertProject(project *models.Project) error {
 	}
 
 	query := `
-		INSERT INTO projects (id, name, git_repo, branch, beads_path, git_strategy, is_perpetual, is_sticky, status, context_json, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO projects (id, org_id, name, git_repo, branch, beads_path, git_strategy, is_perpetual, is_sticky, status, context_json, compliance_json, shared_lessons_opt_out, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
+			org_id = excluded.org_id,
 			name = excluded.name,
 			git_repo = excluded.git_repo,
 			branch = excluded.branch,
@@ -386,11 +164,14 @@ func (d *Database) UpsertProject(project *models.Project) error {
 			is_sticky = excluded.is_sticky,
 			status = excluded.status,
 			context_json = excluded.context_json,
+			compliance_json = excluded.compliance_json,
+			shared_lessons_opt_out = excluded.shared_lessons_opt_out,
 			updated_at = excluded.updated_at
 	`
 
 	_, err := d.db.Exec(query,
 		project.ID,
+		org.Normalize(project.OrgID),
 		project.Name,
 		project.GitRepo,
 		project.Branch,
@@ -400,6 +181,8 @@ func (d *Database) UpsertProject(project *models.Project) error {
 		project.IsSticky,
 		string(project.Status),
 		contextJSON,
+		complianceJSON,
+		project.SharedLessonsOptOut,
 		project.CreatedAt,
 		project.UpdatedAt,
 	)
@@ -411,13 +194,23 @@ func (d *Database) UpsertProject(project *models.Project) error {
 }
 
 func (d *Database) ListProjects() ([]*models.Project, error) {
+	return d.listProjects("", nil)
+}
+
+// ListProjectsInOrg returns the projects of one organization
+func (d *Database) ListProjectsInOrg(orgID string) ([]*models.Project, error) {
+	return d.listProjects("WHERE org_id = ?", []interface{}{org.Normalize(orgID)})
+}
+
+func (d *Database) listProjects(where string, args []interface{}) ([]*models.Project, error) {
 	query := `
-		SELECT id, name, git_repo, branch, beads_path, git_strategy, is_perpetual, is_sticky, status, context_json, created_at, updated_at
+		SELECT id, org_id, name, git_repo, branch, beads_path, git_strategy, is_perpetual, is_sticky, status, context_json, compliance_json, shared_lessons_opt_out, created_at, updated_at
 		FROM projects
+		` + where + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := d.db.Query(query)
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list projects: %w", err)
 	}
@@ -428,10 +221,11 @@ func (d *Database) ListProjects() ([]*models.Project, error) {
 		p := &models.Project{}
 		var status string
 		var gitStrategy sql.NullString
-		var contextJSON sql.NullString
+		var contextJSON, complianceJSON sql.NullString
 		var isSticky sql.NullBool
 		err := rows.Scan(
 			&p.ID,
+			&p.OrgID,
 			&p.Name,
 			&p.GitRepo,
 			&p.Branch,
@@ -441,6 +235,8 @@ func (d *Database) ListProjects() ([]*models.Project, error) {
 			&isSticky,
 			&status,
 			&contextJSON,
+			&complianceJSON,
+			&p.SharedLessonsOptOut,
 			&p.CreatedAt,
 			&p.UpdatedAt,
 		)
@@ -462,6 +258,9 @@ func (d *Database) ListProjects() ([]*models.Project, error) {
 		if p.Context == nil {
 			p.Context = map[string]string{}
 		}
+		if complianceJSON.Valid && complianceJSON.String != "" {
+			_ = json.Unmarshal([]byte(complianceJSON.String), &p.Compliance)
+		}
 		p.Agents = []string{}
 		p.Comments = []models.ProjectComment{}
 		projects = append(projects, p)
@@ -611,8 +410,8 @@ func (d *Database) CreateProvider(provider *internalmodels.Provider) error {
 	provider.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO providers (id, name, type, endpoint, model, description, requires_key, key_id, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO providers (id, name, type, endpoint, model, description, requires_key, key_id, org_id, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err := d.db.Exec(query,
@@ -624,6 +423,7 @@ func (d *Database) CreateProvider(provider *internalmodels.Provider) error {
 		provider.Description,
 		provider.RequiresKey,
 		provider.KeyID,
+		sqlNullString(provider.OrgID),
 		provider.Status,
 		provider.LastHeartbeatAt,
 		provider.LastHeartbeatLatencyMs,
@@ -649,9 +449,14 @@ func (d *Database) UpsertProvider(provider *internalmodels.Provider) error {
 	}
 	provider.UpdatedAt = time.Now()
 
+	tagsJSON, err := encodeProviderTags(provider.Tags)
+	if err != nil {
+		return err
+	}
+
 	query := `
-		INSERT INTO providers (id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, owner_id, is_shared, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, context_window, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO providers (id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, owner_id, is_shared, org_id, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, context_window, tags_json, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(id) DO UPDATE SET
 			name = excluded.name,
 			type = excluded.type,
@@ -667,15 +472,17 @@ func (d *Database) UpsertProvider(provider *internalmodels.Provider) error {
 			key_id = excluded.key_id,
 			owner_id = excluded.owner_id,
 			is_shared = excluded.is_shared,
+			org_id = excluded.org_id,
 			status = excluded.status,
 			last_heartbeat_at = excluded.last_heartbeat_at,
 			last_heartbeat_latency_ms = excluded.last_heartbeat_latency_ms,
 			last_heartbeat_error = excluded.last_heartbeat_error,
 			context_window = excluded.context_window,
+			tags_json = excluded.tags_json,
 			updated_at = excluded.updated_at
 	`
 
-	_, err := d.db.Exec(query,
+	_, err = d.db.Exec(query,
 		provider.ID,
 		provider.Name,
 		provider.Type,
@@ -691,11 +498,13 @@ func (d *Database) UpsertProvider(provider *internalmodels.Provider) error {
 		provider.KeyID,
 		provider.OwnerID,
 		provider.IsShared,
+		sqlNullString(provider.OrgID),
 		provider.Status,
 		provider.LastHeartbeatAt,
 		provider.LastHeartbeatLatencyMs,
 		provider.LastHeartbeatError,
 		provider.ContextWindow,
+		tagsJSON,
 		provider.CreatedAt,
 		provider.UpdatedAt,
 	)
@@ -733,12 +542,13 @@ func (d *Database) DeleteAllAgents() error {
 // GetProvider retrieves a provider by ID
 func (d *Database) GetProvider(id string) (*internalmodels.Provider, error) {
 	query := `
-		SELECT id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, context_window, created_at, updated_at
+		SELECT id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, org_id, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, context_window, tags_json, created_at, updated_at
 		FROM providers
 		WHERE id = ?
 	`
 
 	provider := &internalmodels.Provider{}
+	var orgID, tagsJSON sql.NullString
 	err := d.db.QueryRow(query, id).Scan(
 		&provider.ID,
 		&provider.Name,
@@ -753,11 +563,13 @@ func (d *Database) GetProvider(id string) (*internalmodels.Provider, error) {
 		&provider.Description,
 		&provider.RequiresKey,
 		&provider.KeyID,
+		&orgID,
 		&provider.Status,
 		&provider.LastHeartbeatAt,
 		&provider.LastHeartbeatLatencyMs,
 		&provider.LastHeartbeatError,
 		&provider.ContextWindow,
+		&tagsJSON,
 		&provider.CreatedAt,
 		&provider.UpdatedAt,
 	)
@@ -768,89 +580,47 @@ func (d *Database) GetProvider(id string) (*internalmodels.Provider, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider: %w", err)
 	}
+	provider.OrgID = orgID.String
+	provider.Tags = decodeProviderTags(tagsJSON)
 
 	return provider, nil
 }
 
 // ListProviders retrieves all providers
 func (d *Database) ListProviders() ([]*internalmodels.Provider, error) {
-	query := `
-		SELECT id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, owner_id, is_shared, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, created_at, updated_at
-		FROM providers
-		ORDER BY created_at DESC
-	`
-
-	rows, err := d.db.Query(query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list providers: %w", err)
-	}
-	defer rows.Close()
-
-	var providers []*internalmodels.Provider
-	for rows.Next() {
-		provider := &internalmodels.Provider{}
-		var ownerID sql.NullString
-		var isShared sql.NullBool
-		err := rows.Scan(
-			&provider.ID,
-			&provider.Name,
-			&provider.Type,
-			&provider.Endpoint,
-			&provider.Model,
-			&provider.ConfiguredModel,
-			&provider.SelectedModel,
-			&provider.SelectionReason,
-			&provider.ModelScore,
-			&provider.SelectedGPU,
-			&provider.Description,
-			&provider.RequiresKey,
-			&provider.KeyID,
-			&ownerID,
-			&isShared,
-			&provider.Status,
-			&provider.LastHeartbeatAt,
-			&provider.LastHeartbeatLatencyMs,
-			&provider.LastHeartbeatError,
-			&provider.CreatedAt,
-			&provider.UpdatedAt,
-		)
-		if ownerID.Valid {
-			provider.OwnerID = ownerID.String
-		}
-		if isShared.Valid {
-			provider.IsShared = isShared.Bool
-		} else {
-			provider.IsShared = true // Default to shared for backwards compat
-		}
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan provider: %w", err)
-		}
-		providers = append(providers, provider)
-	}
-
-	return providers, nil
+	return d.listProviders("", nil)
 }
 
 // ListProvidersForUser retrieves providers accessible to a specific user
 // Returns providers owned by the user OR shared providers
 func (d *Database) ListProvidersForUser(userID string) ([]*internalmodels.Provider, error) {
+	return d.listProviders("WHERE owner_id = ? OR is_shared = 1 OR owner_id IS NULL", []interface{}{userID})
+}
+
+// ListProvidersInOrg retrieves the providers an organization's projects may
+// use: its own and those shared with every organization
+func (d *Database) ListProvidersInOrg(orgID string) ([]*internalmodels.Provider, error) {
+	return d.listProviders("WHERE org_id = ? OR org_id IS NULL", []interface{}{orgID})
+}
+
+func (d *Database) listProviders(where string, args []interface{}) ([]*internalmodels.Provider, error) {
 	query := `
-		SELECT id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, owner_id, is_shared, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, created_at, updated_at
+		SELECT id, name, type, endpoint, model, configured_model, selected_model, selection_reason, model_score, selected_gpu, description, requires_key, key_id, owner_id, is_shared, org_id, status, last_heartbeat_at, last_heartbeat_latency_ms, last_heartbeat_error, tags_json, created_at, updated_at
 		FROM providers
-		WHERE owner_id = ? OR is_shared = 1 OR owner_id IS NULL
+		` + where + `
 		ORDER BY created_at DESC
 	`
 
-	rows, err := d.db.Query(query, userID)
+	rows, err := d.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list providers for user: %w", err)
+		return nil, fmt.Errorf("failed to list providers: %w", err)
 	}
 	defer rows.Close()
 
 	var providers []*internalmodels.Provider
 	for rows.Next() {
 		provider := &internalmodels.Provider{}
-		var ownerID sql.NullString
+		var ownerID, orgID, tagsJSON sql.NullString
 		var isShared sql.NullBool
 		err := rows.Scan(
 			&provider.ID,
@@ -868,10 +638,12 @@ func (d *Database) ListProvidersForUser(userID string) ([]*internalmodels.Provid
 			&provider.KeyID,
 			&ownerID,
 			&isShared,
+			&orgID,
 			&provider.Status,
 			&provider.LastHeartbeatAt,
 			&provider.LastHeartbeatLatencyMs,
 			&provider.LastHeartbeatError,
+			&tagsJSON,
 			&provider.CreatedAt,
 			&provider.UpdatedAt,
 		)
@@ -885,8 +657,10 @@ func (d *Database) ListProvidersForUser(userID string) ([]*internalmodels.Provid
 		if isShared.Valid {
 			provider.IsShared = isShared.Bool
 		} else {
-			provider.IsShared = true
+			provider.IsShared = true // Default to shared for backwards compat
 		}
+		provider.OrgID = orgID.String
+		provider.Tags = decodeProviderTags(tagsJSON)
 
 		providers = append(providers, provider)
 	}
@@ -894,6 +668,26 @@ func (d *Database) ListProvidersForUser(userID string) ([]*internalmodels.Provid
 	return providers, nil
 }
 
+// encodeProviderTags stores provider tags as a JSON list, or NULL when there are none
+func encodeProviderTags(tags []string) (sql.NullString, error) {
+	if len(tags) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(tags)
+	if err != nil {
+		return sql.NullString{}, fmt.Errorf("failed to marshal provider tags: %w", err)
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func decodeProviderTags(tagsJSON sql.NullString) []string {
+	var tags []string
+	if tagsJSON.Valid && tagsJSON.String != "" {
+		_ = json.Unmarshal([]byte(tagsJSON.String), &tags)
+	}
+	return tags
+}
+
 // UpdateProvider updates a provider
 func (d *Database) UpdateProvider(provider *internalmodels.Provider) error {
 	provider.UpdatedAt = time.Now()
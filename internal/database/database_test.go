@@ -4,12 +4,32 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/jordanhubbard/loom/internal/artifacts"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/auth"
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/cistatus"
+	"github.com/jordanhubbard/loom/internal/evaluation"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/issuesync"
 	"github.com/jordanhubbard/loom/internal/memory"
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+	"github.com/jordanhubbard/loom/internal/policy"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/prreview"
+	"github.com/jordanhubbard/loom/internal/reports"
+	"github.com/jordanhubbard/loom/internal/scheduler"
+	"github.com/jordanhubbard/loom/internal/search"
 	"github.com/jordanhubbard/loom/internal/workflow"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
@@ -1984,7 +2004,7 @@ func TestUpdateAggregatedActivity(t *testing.T) {
 		t.Fatalf("CreateActivity failed: %v", err)
 	}
 
-	if err := db.UpdateAggregatedActivity("act-upd-agg", 10); err != nil {
+	if err := db.UpdateAggregatedActivity("act-upd-agg", 10, time.Now()); err != nil {
 		t.Fatalf("UpdateAggregatedActivity failed: %v", err)
 	}
 
@@ -2000,6 +2020,154 @@ func TestUpdateAggregatedActivity(t *testing.T) {
 	}
 }
 
+func TestGetRecentAggregatableActivity_SlidesWithLatestEvent(t *testing.T) {
+	db := newTestDB(t)
+
+	a := makeTestActivity("act-slide")
+	a.Timestamp = time.Now().Add(-time.Hour)
+	a.AggregationKey = "slide.key"
+	a.IsAggregated = true
+	if err := db.CreateActivity(a); err != nil {
+		t.Fatalf("CreateActivity failed: %v", err)
+	}
+	since := time.Now().Add(-5 * time.Minute)
+	if got, err := db.GetRecentAggregatableActivity("slide.key", since); err != nil || got != nil {
+		t.Fatalf("expected no activity with a recent event, got %+v, %v", got, err)
+	}
+
+	latest := time.Now().Add(-time.Minute)
+	if err := db.UpdateAggregatedActivity("act-slide", 2, latest); err != nil {
+		t.Fatalf("UpdateAggregatedActivity failed: %v", err)
+	}
+	got, err := db.GetRecentAggregatableActivity("slide.key", since)
+	if err != nil || got == nil {
+		t.Fatalf("GetRecentAggregatableActivity = %+v, %v", got, err)
+	}
+	if !got.LastEventAt.Equal(latest) || got.Timestamp.Equal(got.LastEventAt) {
+		t.Errorf("LastEventAt = %v, Timestamp = %v", got.LastEventAt, got.Timestamp)
+	}
+}
+
+func TestActivityEvents(t *testing.T) {
+	db := newTestDB(t)
+
+	a := makeTestActivity("act-events")
+	a.IsAggregated = true
+	if err := db.CreateActivity(a); err != nil {
+		t.Fatalf("CreateActivity failed: %v", err)
+	}
+	base := time.Now().Add(-time.Minute)
+	for i := 0; i < 3; i++ {
+		if err := db.CreateActivityEvent(&ActivityEvent{
+			ID:           fmt.Sprintf("ev-%d", i),
+			ActivityID:   "act-events",
+			Timestamp:    base.Add(time.Duration(i) * time.Second),
+			ActorID:      "agent-3",
+			ResourceID:   fmt.Sprintf("file-%d.go", i),
+			MetadataJSON: `{"path":"x"}`,
+		}); err != nil {
+			t.Fatalf("CreateActivityEvent failed: %v", err)
+		}
+	}
+
+	first, err := db.ListActivityEvents(ActivityEventFilters{ActivityID: "act-events", Limit: 2})
+	if err != nil || len(first) != 2 || first[0].ID != "ev-0" || first[0].ActorID != "agent-3" {
+		t.Fatalf("first page = %+v, %v", first, err)
+	}
+	last := first[1]
+	rest, err := db.ListActivityEvents(ActivityEventFilters{ActivityID: "act-events", Limit: 2, After: []interface{}{last.Timestamp, last.ID}})
+	if err != nil || len(rest) != 1 || rest[0].ID != "ev-2" {
+		t.Fatalf("second page = %+v, %v", rest, err)
+	}
+	newest, err := db.ListActivityEvents(ActivityEventFilters{ActivityID: "act-events", Limit: 1, Descending: true})
+	if err != nil || len(newest) != 1 || newest[0].ID != "ev-2" {
+		t.Fatalf("newest = %+v, %v", newest, err)
+	}
+	if n, err := db.CountActivityEvents("act-events"); err != nil || n != 3 {
+		t.Fatalf("CountActivityEvents = %d, %v", n, err)
+	}
+
+	got, err := db.GetActivity("act-events")
+	if err != nil || got == nil || got.ID != "act-events" {
+		t.Fatalf("GetActivity = %+v, %v", got, err)
+	}
+	if got, err := db.GetActivity("missing"); err != nil || got != nil {
+		t.Fatalf("GetActivity(missing) = %+v, %v", got, err)
+	}
+
+	// Events go with their activity
+	if err := db.CompactActivities(nil, []string{"act-events"}); err != nil {
+		t.Fatalf("CompactActivities failed: %v", err)
+	}
+	if n, err := db.CountActivityEvents("act-events"); err != nil || n != 0 {
+		t.Errorf("%d events outlived their activity: %v", n, err)
+	}
+}
+
+func TestCompactActivities(t *testing.T) {
+	db := newTestDB(t)
+	ensureUserExists(t, db, "user-ret", "userret")
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+
+	for i, id := range []string{"act-old-1", "act-old-2", "act-old-unread", "act-new"} {
+		a := makeTestActivity(id)
+		a.Timestamp = old.Add(time.Duration(i) * time.Minute)
+		if id == "act-new" {
+			a.Timestamp = now
+		}
+		if err := db.CreateActivity(a); err != nil {
+			t.Fatalf("CreateActivity failed: %v", err)
+		}
+	}
+	if err := db.CreateNotification(&Notification{
+		ID: "notif-ret", UserID: "user-ret", ActivityID: "act-old-unread", EventType: "bead.created",
+		Title: "New Bead", Status: "unread", Priority: "normal", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateNotification failed: %v", err)
+	}
+
+	expired, err := db.ListExpiredActivities(now.Add(-24*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListExpiredActivities failed: %v", err)
+	}
+	if len(expired) != 2 || expired[0].ID != "act-old-1" || expired[1].ID != "act-old-2" {
+		t.Fatalf("expected the two old activities without unread notifications, got %+v", expired)
+	}
+
+	rollup := &ActivityRollup{
+		Key: "default|bead.created", OrgID: "default", EventType: "bead.created", ResourceType: "bead",
+		PeriodStart: old.Truncate(time.Hour), EventCount: 2, FirstAt: old, LastAt: old.Add(time.Minute), UpdatedAt: now,
+	}
+	if err := db.CompactActivities([]*ActivityRollup{rollup}, []string{"act-old-1", "act-old-2"}); err != nil {
+		t.Fatalf("CompactActivities failed: %v", err)
+	}
+	// Compacting into the same key adds to its count
+	again := *rollup
+	again.EventCount, again.FirstAt = 3, old.Add(-time.Hour)
+	if err := db.CompactActivities([]*ActivityRollup{&again}, nil); err != nil {
+		t.Fatalf("CompactActivities failed: %v", err)
+	}
+
+	if n, _ := db.CountActivities(ActivityFilters{}); n != 2 {
+		t.Errorf("expected 2 activities left, got %d", n)
+	}
+	rollups, err := db.ListActivityRollups(ActivityRollupFilters{OrgID: "default"})
+	if err != nil {
+		t.Fatalf("ListActivityRollups failed: %v", err)
+	}
+	if len(rollups) != 1 || rollups[0].EventCount != 5 || !rollups[0].FirstAt.Equal(again.FirstAt) || !rollups[0].LastAt.Equal(rollup.LastAt) {
+		t.Fatalf("unexpected rollups %+v", rollups)
+	}
+
+	if n, err := db.DeleteActivityRollups(old); err != nil || n != 0 {
+		t.Errorf("expected no rollups before their last event to be deleted, got %d, %v", n, err)
+	}
+	if n, err := db.DeleteActivityRollups(now); err != nil || n != 1 {
+		t.Errorf("expected the rollup to be deleted, got %d, %v", n, err)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // 10. Notifications: CreateNotification, ListNotifications,
 //     MarkNotificationRead, MarkAllNotificationsRead
@@ -2087,6 +2255,88 @@ func TestListNotifications_LimitAndOffset(t *testing.T) {
 	}
 }
 
+func TestQueryNotifications_PriorityFilterSortAndCount(t *testing.T) {
+	db := newTestDB(t)
+	ensureUserExists(t, db, "user-qn", "user_qn")
+	base := time.Now().Add(-time.Hour)
+	for i, priority := range []string{"low", "critical", "normal", "high", "high"} {
+		n := &Notification{
+			ID: fmt.Sprintf("notif-qn-%d", i), UserID: "user-qn", EventType: "e",
+			Title: "T", Message: "M", Status: "unread", Priority: priority,
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.CreateNotification(n); err != nil {
+			t.Fatalf("CreateNotification failed: %v", err)
+		}
+	}
+
+	// Most urgent first, newest first among equals
+	notifs, err := db.QueryNotifications("user-qn", NotificationFilters{SortBy: "priority", Limit: 3})
+	if err != nil {
+		t.Fatalf("QueryNotifications failed: %v", err)
+	}
+	var got []string
+	for _, n := range notifs {
+		got = append(got, n.ID)
+	}
+	if fmt.Sprint(got) != "[notif-qn-1 notif-qn-4 notif-qn-3]" {
+		t.Errorf("unexpected order: %v", got)
+	}
+
+	filters := NotificationFilters{Priority: "high", Limit: 1}
+	total, err := db.CountNotifications("user-qn", filters)
+	if err != nil {
+		t.Fatalf("CountNotifications failed: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("expected 2 high-priority notifications regardless of limit, got %d", total)
+	}
+	notifs, err = db.QueryNotifications("user-qn", filters)
+	if err != nil || len(notifs) != 1 || notifs[0].Priority != "high" {
+		t.Errorf("expected one high-priority notification, got %v (err %v)", notifs, err)
+	}
+}
+
+func TestQueryNotifications_KeysetPages(t *testing.T) {
+	db := newTestDB(t)
+	ensureUserExists(t, db, "user-ks", "user_ks")
+	at := time.Now().Add(-time.Hour)
+	// Equal priorities and timestamps leave only the ID to order by
+	for i, priority := range []string{"high", "low", "high", "high", "low"} {
+		n := &Notification{
+			ID: fmt.Sprintf("notif-ks-%d", i), UserID: "user-ks", EventType: "e",
+			Title: "T", Message: "M", Status: "unread", Priority: priority, CreatedAt: at,
+		}
+		if err := db.CreateNotification(n); err != nil {
+			t.Fatalf("CreateNotification failed: %v", err)
+		}
+	}
+
+	var got []string
+	filters := NotificationFilters{SortBy: "priority", Limit: 2}
+	for page := 0; page < 5; page++ {
+		notifs, err := db.QueryNotifications("user-ks", filters)
+		if err != nil {
+			t.Fatalf("QueryNotifications failed: %v", err)
+		}
+		if len(notifs) == 0 {
+			break
+		}
+		for _, n := range notifs {
+			got = append(got, n.ID)
+		}
+		last := notifs[len(notifs)-1]
+		filters.After = []interface{}{NotificationPriorityRank(last.Priority), last.CreatedAt, last.ID}
+	}
+	if fmt.Sprint(got) != "[notif-ks-3 notif-ks-2 notif-ks-0 notif-ks-4 notif-ks-1]" {
+		t.Errorf("unexpected pages: %v", got)
+	}
+
+	if _, err := db.QueryNotifications("user-ks", NotificationFilters{After: []interface{}{at}}); err == nil {
+		t.Error("expected an error for a keyset of the wrong length")
+	}
+}
+
 func TestMarkNotificationRead(t *testing.T) {
 	db := newTestDB(t)
 	ensureUserExists(t, db, "user-mr", "user_mr")
@@ -2098,7 +2348,7 @@ func TestMarkNotificationRead(t *testing.T) {
 		t.Fatalf("CreateNotification failed: %v", err)
 	}
 
-	if err := db.MarkNotificationRead("notif-mr"); err != nil {
+	if _, err := db.MarkNotificationRead("notif-mr"); err != nil {
 		t.Fatalf("MarkNotificationRead failed: %v", err)
 	}
 
@@ -2127,7 +2377,7 @@ func TestMarkAllNotificationsRead(t *testing.T) {
 		}
 	}
 
-	if err := db.MarkAllNotificationsRead("user-mar"); err != nil {
+	if _, err := db.MarkAllNotificationsRead("user-mar"); err != nil {
 		t.Fatalf("MarkAllNotificationsRead failed: %v", err)
 	}
 
@@ -2148,6 +2398,123 @@ func TestMarkAllNotificationsRead(t *testing.T) {
 	}
 }
 
+func TestNotificationUnreadCounters(t *testing.T) {
+	db := newTestDB(t)
+	ensureUserExists(t, db, "user-uc", "user_uc")
+	for i, n := range []struct{ project, priority, status string }{
+		{"proj-1", "high", "unread"},
+		{"proj-1", "high", "unread"},
+		{"proj-1", "low", "unread"},
+		{"", "critical", "unread"},
+		{"proj-2", "high", "read"},
+	} {
+		if err := db.CreateNotification(&Notification{
+			ID: fmt.Sprintf("notif-uc-%d", i), UserID: "user-uc", ProjectID: n.project, EventType: "e",
+			Title: "T", Message: "M", Status: n.status, Priority: n.priority, CreatedAt: time.Now(),
+		}); err != nil {
+			t.Fatalf("CreateNotification failed: %v", err)
+		}
+	}
+	counts := func() string {
+		t.Helper()
+		got, err := db.GetUnreadCounts("user-uc")
+		if err != nil {
+			t.Fatalf("GetUnreadCounts failed: %v", err)
+		}
+		var out []string
+		for _, c := range got {
+			out = append(out, fmt.Sprintf("%s/%s=%d", c.ProjectID, c.Priority, c.Count))
+		}
+		return strings.Join(out, " ")
+	}
+	if got := counts(); got != "/critical=1 proj-1/high=2 proj-1/low=1" {
+		t.Fatalf("unexpected counts after create: %s", got)
+	}
+
+	deltas, err := db.MarkNotificationRead("notif-uc-0")
+	if err != nil || len(deltas) != 1 || deltas[0].Count != -1 || deltas[0].Priority != "high" {
+		t.Fatalf("MarkNotificationRead = %+v, %v", deltas, err)
+	}
+	if deltas, _ := db.MarkNotificationRead("notif-uc-0"); len(deltas) != 0 {
+		t.Errorf("expected reading a read notification to change nothing, got %+v", deltas)
+	}
+	if _, err := db.ArchiveNotification("notif-uc-2", "user-uc"); err != nil {
+		t.Fatalf("ArchiveNotification failed: %v", err)
+	}
+	if _, err := db.ArchiveNotification("notif-uc-2", "user-uc"); err == nil {
+		t.Error("expected archiving twice to fail")
+	}
+	if got := counts(); got != "/critical=1 proj-1/high=1" {
+		t.Fatalf("unexpected counts after read and archive: %s", got)
+	}
+
+	until := time.Now().Add(-time.Second)
+	if _, err := db.SnoozeNotification("notif-uc-3", "user-uc", until); err != nil {
+		t.Fatalf("SnoozeNotification failed: %v", err)
+	}
+	if got := counts(); got != "proj-1/high=1" {
+		t.Fatalf("unexpected counts after snooze: %s", got)
+	}
+	woken, deltas, err := db.WakeSnoozedNotifications(time.Now())
+	if err != nil || len(woken) != 1 || len(deltas) != 1 || deltas[0].Count != 1 {
+		t.Fatalf("WakeSnoozedNotifications = %d, %+v, %v", len(woken), deltas, err)
+	}
+
+	deltas, err = db.MarkAllNotificationsRead("user-uc")
+	if err != nil || len(deltas) != 2 {
+		t.Fatalf("MarkAllNotificationsRead = %+v, %v", deltas, err)
+	}
+	if got := counts(); got != "" {
+		t.Errorf("expected no unread notifications, got %s", got)
+	}
+	if notifs, _ := db.QueryNotifications("user-uc", NotificationFilters{ProjectID: "proj-2"}); len(notifs) != 1 {
+		t.Errorf("expected one notification of proj-2, got %d", len(notifs))
+	}
+}
+
+func TestNotificationDevices(t *testing.T) {
+	db := newTestDB(t)
+	ensureUserExists(t, db, "user-dev-1", "user_dev_1")
+	ensureUserExists(t, db, "user-dev-2", "user_dev_2")
+
+	now := time.Now()
+	device := &NotificationDevice{ID: "dev-1", UserID: "user-dev-1", Platform: "fcm", Token: "tok-1", Name: "Pixel", CreatedAt: now, LastSeenAt: now}
+	if err := db.SaveNotificationDevice(device); err != nil {
+		t.Fatalf("SaveNotificationDevice failed: %v", err)
+	}
+	if err := db.SaveNotificationDevice(&NotificationDevice{ID: "dev-2", UserID: "user-dev-1", Platform: "apns", Token: "tok-2", CreatedAt: now, LastSeenAt: now}); err != nil {
+		t.Fatalf("SaveNotificationDevice failed: %v", err)
+	}
+
+	// Registering a token again moves it and keeps its ID
+	moved := &NotificationDevice{ID: "dev-3", UserID: "user-dev-2", Platform: "fcm", Token: "tok-1", Name: "Pixel", CreatedAt: now, LastSeenAt: now}
+	if err := db.SaveNotificationDevice(moved); err != nil {
+		t.Fatalf("SaveNotificationDevice failed: %v", err)
+	}
+	if moved.ID != "dev-1" {
+		t.Errorf("expected the registered device to keep ID dev-1, got %s", moved.ID)
+	}
+	if devices, _ := db.ListNotificationDevices("user-dev-1"); len(devices) != 1 || devices[0].Token != "tok-2" {
+		t.Fatalf("expected only tok-2 left to user-dev-1, got %+v", devices)
+	}
+	if devices, _ := db.ListNotificationDevices("user-dev-2"); len(devices) != 1 || devices[0].UserID != "user-dev-2" {
+		t.Fatalf("expected tok-1 to belong to user-dev-2, got %+v", devices)
+	}
+
+	if err := db.DeleteNotificationDevice("dev-2", "user-dev-2"); err == nil {
+		t.Error("expected another user's device not to be deleted")
+	}
+	if err := db.DeleteNotificationDevice("dev-2", "user-dev-1"); err != nil {
+		t.Fatalf("DeleteNotificationDevice failed: %v", err)
+	}
+	if err := db.DeleteNotificationDeviceByToken("tok-1"); err != nil {
+		t.Fatalf("DeleteNotificationDeviceByToken failed: %v", err)
+	}
+	if devices, _ := db.ListNotificationDevices("user-dev-2"); len(devices) != 0 {
+		t.Errorf("expected no devices left, got %+v", devices)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // 11. Notification Preferences
 // ---------------------------------------------------------------------------
@@ -3129,10 +3496,232 @@ func TestSearchLessonsBySimilarity_NoEmbedding(t *testing.T) {
 	}
 }
 
+func TestSupersedeLesson_HidesAndReinstates(t *testing.T) {
+	db := newTestDB(t)
+
+	for i, id := range []string{"sup-old", "sup-new"} {
+		lesson := &models.Lesson{
+			ID:        id,
+			ProjectID: "proj-sup",
+			Category:  "test",
+			Title:     id,
+			Detail:    "Detail for " + id,
+			CreatedAt: time.Now().Add(time.Duration(i-2) * time.Minute),
+		}
+		if err := db.StoreLessonWithEmbedding(lesson, []float32{1, 0}); err != nil {
+			t.Fatalf("StoreLessonWithEmbedding(%s) failed: %v", id, err)
+		}
+	}
+	if err := db.SupersedeLesson("sup-old", "sup-new", time.Now()); err != nil {
+		t.Fatalf("SupersedeLesson failed: %v", err)
+	}
+
+	current, err := db.ListLessonsWithEmbeddings("proj-sup", "", 10)
+	if err != nil {
+		t.Fatalf("ListLessonsWithEmbeddings failed: %v", err)
+	}
+	if len(current) != 1 || current[0].ID != "sup-new" || len(current[0].Embedding) != 2 {
+		t.Fatalf("expected only the newer lesson with its embedding, got %+v", current)
+	}
+	if lessons, _ := db.GetLessonsForProject("proj-sup", 10, 0); len(lessons) != 1 {
+		t.Errorf("expected the superseded lesson left out of recent lessons, got %d", len(lessons))
+	}
+	if results, _ := db.SearchLessonsBySimilarity("proj-sup", []float32{1, 0}, 5); len(results) != 1 {
+		t.Errorf("expected the superseded lesson left out of searches, got %d", len(results))
+	}
+
+	superseded, err := db.ListSupersededLessons("proj-sup", 0)
+	if err != nil {
+		t.Fatalf("ListSupersededLessons failed: %v", err)
+	}
+	if len(superseded) != 1 || superseded[0].SupersededBy != "sup-new" || superseded[0].SupersededAt == nil {
+		t.Fatalf("expected sup-old superseded by sup-new, got %+v", superseded)
+	}
+
+	if err := db.ReinstateLesson("sup-old"); err != nil {
+		t.Fatalf("ReinstateLesson failed: %v", err)
+	}
+	if lessons, _ := db.GetLessonsForProject("proj-sup", 10, 0); len(lessons) != 2 {
+		t.Errorf("expected the reinstated lesson back, got %d", len(lessons))
+	}
+}
+
+func TestRecordLessonInjections_ScoresAndReports(t *testing.T) {
+	db := newTestDB(t)
+
+	for _, id := range []string{"eff-good", "eff-bad", "eff-rare"} {
+		if err := db.CreateLesson(&models.Lesson{ID: id, ProjectID: "proj-eff", Category: "test", Title: id, Detail: id}); err != nil {
+			t.Fatalf("CreateLesson(%s) failed: %v", id, err)
+		}
+	}
+	// eff-good goes into 4 dispatches that succeed, eff-bad into 4 that
+	// fail, and eff-rare into one
+	var injections []*models.LessonInjection
+	for i := 0; i < 4; i++ {
+		injections = append(injections,
+			&models.LessonInjection{LessonID: "eff-good", DispatchID: fmt.Sprintf("ok-%d", i), ProjectID: "proj-eff", Outcome: "completed", Succeeded: true},
+			&models.LessonInjection{LessonID: "eff-bad", DispatchID: fmt.Sprintf("fail-%d", i), ProjectID: "proj-eff", Outcome: "max_iterations"},
+		)
+	}
+	injections = append(injections, &models.LessonInjection{LessonID: "eff-rare", DispatchID: "ok-0", ProjectID: "proj-eff", Outcome: "completed", Succeeded: true})
+	if err := db.RecordLessonInjections(injections); err != nil {
+		t.Fatalf("RecordLessonInjections failed: %v", err)
+	}
+	// Recording a dispatch again replaces its outcome
+	if err := db.RecordLessonInjections(injections[:1]); err != nil {
+		t.Fatalf("RecordLessonInjections failed: %v", err)
+	}
+
+	lessons, err := db.GetLessonsByID([]string{"eff-good", "eff-bad", "eff-rare"})
+	if err != nil || len(lessons) != 3 {
+		t.Fatalf("GetLessonsByID failed: %v", err)
+	}
+	good, bad, rare := lessons[0].RelevanceScore, lessons[1].RelevanceScore, lessons[2].RelevanceScore
+	if !(good > rare && rare > 1.0 && bad < 1.0) {
+		t.Errorf("expected scores good > rare > 1 > bad, got %.2f, %.2f, %.2f", good, rare, bad)
+	}
+
+	report, err := db.LessonEffectivenessReport("proj-eff", 0, 0)
+	if err != nil {
+		t.Fatalf("LessonEffectivenessReport failed: %v", err)
+	}
+	if report.Dispatches != 8 || report.BaselineSuccessRate != 0.5 {
+		t.Errorf("expected 8 dispatches half succeeding, got %d at %.2f", report.Dispatches, report.BaselineSuccessRate)
+	}
+	if len(report.Helpful) != 1 || report.Helpful[0].Lesson.ID != "eff-good" || report.Helpful[0].Lift != 0.5 {
+		t.Errorf("expected eff-good helpful, got %+v", report.Helpful)
+	}
+	if len(report.Useless) != 1 || report.Useless[0].Lesson.ID != "eff-bad" || report.Useless[0].Injections != 4 {
+		t.Errorf("expected eff-bad useless and eff-rare left out, got %+v", report.Useless)
+	}
+}
+
+func TestSharedLessons_PromoteApproveAndSearch(t *testing.T) {
+	db := newTestDB(t)
+
+	if err := db.CreateLesson(&models.Lesson{ID: "proj-lesson", ProjectID: "proj-a", Category: "test", Title: "JSON for YAML", Detail: "Model x emits JSON when asked for YAML"}); err != nil {
+		t.Fatalf("CreateLesson failed: %v", err)
+	}
+	proposed := &models.Lesson{ID: "shared-1", OrgID: "acme", Category: "test", Title: "JSON for YAML", Detail: "Model x emits JSON when asked for YAML",
+		ShareStatus: models.ShareStatusProposed, PromotedFrom: "proj-lesson"}
+	if err := db.StoreLessonWithEmbedding(proposed, []float32{1, 0}); err != nil {
+		t.Fatalf("StoreLessonWithEmbedding failed: %v", err)
+	}
+
+	// Proposals stay out of searches until approved
+	query := map[string][]float32{"": {1, 0}}
+	if lessons, _ := db.SearchSharedLessonsByEmbeddings("acme", query, 5); len(lessons) != 0 {
+		t.Errorf("expected a proposal not to be searched, got %d", len(lessons))
+	}
+	found, err := db.SharedLessonPromotedFrom("acme", "proj-lesson")
+	if err != nil || found == nil || found.ID != "shared-1" {
+		t.Fatalf("expected the promotion found, got %+v, %v", found, err)
+	}
+
+	if err := db.ApproveSharedLesson("other", "shared-1"); err == nil {
+		t.Error("expected approval in another organization to fail")
+	}
+	if err := db.ApproveSharedLesson("acme", "shared-1"); err != nil {
+		t.Fatalf("ApproveSharedLesson failed: %v", err)
+	}
+	lessons, err := db.SearchSharedLessonsByEmbeddings("acme", query, 5)
+	if err != nil || len(lessons) != 1 || lessons[0].PromotedFrom != "proj-lesson" || lessons[0].ShareStatus != models.ShareStatusShared {
+		t.Fatalf("expected the approved lesson searched, got %+v, %v", lessons, err)
+	}
+	if lessons, _ := db.ListSharedLessons("acme", models.ShareStatusProposed, 0); len(lessons) != 0 {
+		t.Errorf("expected no proposals left, got %d", len(lessons))
+	}
+
+	if err := db.DeleteSharedLesson("acme", "proj-lesson"); err == nil {
+		t.Error("expected a project lesson not to be deleted as shared")
+	}
+	if err := db.DeleteSharedLesson("acme", "shared-1"); err != nil {
+		t.Fatalf("DeleteSharedLesson failed: %v", err)
+	}
+	if lessons, _ := db.ListSharedLessons("acme", "", 0); len(lessons) != 0 {
+		t.Errorf("expected the shared lesson retired, got %d", len(lessons))
+	}
+}
+
+func TestUpsertProject_SharedLessonsOptOut(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.UpsertProject(&models.Project{ID: "proj-opt", Name: "Opt", Status: models.ProjectStatusOpen, SharedLessonsOptOut: true}); err != nil {
+		t.Fatalf("UpsertProject failed: %v", err)
+	}
+	projects, err := db.ListProjects()
+	if err != nil || len(projects) != 1 || !projects[0].SharedLessonsOptOut {
+		t.Errorf("expected the opt-out kept, got %+v, %v", projects, err)
+	}
+}
+
 // ============================================================
 // 17. Additional ListActivities filter tests
 // ============================================================
 
+func TestCountActivities_AndAscendingOrder(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 4; i++ {
+		a := makeTestActivity(fmt.Sprintf("act-cnt-%d", i))
+		a.Timestamp = base.Add(time.Duration(i) * time.Minute)
+		if i == 3 {
+			a.EventType = "agent.started"
+		}
+		if err := db.CreateActivity(a); err != nil {
+			t.Fatalf("CreateActivity failed: %v", err)
+		}
+	}
+
+	filters := ActivityFilters{EventType: "bead.created", Limit: 2, Ascending: true}
+	total, err := db.CountActivities(filters)
+	if err != nil {
+		t.Fatalf("CountActivities failed: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("expected 3 matching activities regardless of limit, got %d", total)
+	}
+
+	activities, err := db.ListActivities(filters)
+	if err != nil {
+		t.Fatalf("ListActivities failed: %v", err)
+	}
+	if len(activities) != 2 || activities[0].ID != "act-cnt-0" || activities[1].ID != "act-cnt-1" {
+		t.Errorf("expected the two oldest activities first, got %d", len(activities))
+	}
+}
+
+func TestListActivities_KeysetPages(t *testing.T) {
+	db := newTestDB(t)
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		a := makeTestActivity(fmt.Sprintf("act-ks-%d", i))
+		a.Timestamp = base.Add(time.Duration(i/2) * time.Minute)
+		if err := db.CreateActivity(a); err != nil {
+			t.Fatalf("CreateActivity failed: %v", err)
+		}
+	}
+
+	var got []string
+	filters := ActivityFilters{Limit: 2, Ascending: true}
+	for page := 0; page < 5; page++ {
+		activities, err := db.ListActivities(filters)
+		if err != nil {
+			t.Fatalf("ListActivities failed: %v", err)
+		}
+		if len(activities) == 0 {
+			break
+		}
+		for _, a := range activities {
+			got = append(got, a.ID)
+		}
+		last := activities[len(activities)-1]
+		filters.After = []interface{}{last.Timestamp, last.ID}
+	}
+	if fmt.Sprint(got) != "[act-ks-0 act-ks-1 act-ks-2 act-ks-3 act-ks-4]" {
+		t.Errorf("unexpected pages: %v", got)
+	}
+}
+
 func TestListActivities_ActorFilter(t *testing.T) {
 	db := newTestDB(t)
 	ensureProjectExists(t, db, "proj-af")
@@ -3267,7 +3856,7 @@ func TestListNotifications_StatusFilter(t *testing.T) {
 	}
 
 	// Mark second as read
-	if err := db.MarkNotificationRead("notif-ur2"); err != nil {
+	if _, err := db.MarkNotificationRead("notif-ur2"); err != nil {
 		t.Fatalf("MarkNotificationRead failed: %v", err)
 	}
 
@@ -3285,7 +3874,7 @@ func TestListNotifications_StatusFilter(t *testing.T) {
 }
 
 // ============================================================
-// 20. isAlterColumnExistsError tests (lessons.go)
+// 20. isAlterColumnExistsError tests (migrate.go)
 // ============================================================
 
 func TestIsAlterColumnExistsError(t *testing.T) {
@@ -3451,3 +4040,1390 @@ func TestListUsers_MultipleUsers(t *testing.T) {
 	}
 }
 
+
+// ============================================================
+// 24. Audit log store
+// ============================================================
+
+func TestAuditLog_ChainSurvivesRoundTrip(t *testing.T) {
+	db := newTestDB(t)
+
+	logger, err := audit.NewLogger(db)
+	if err != nil {
+		t.Fatalf("NewLogger failed: %v", err)
+	}
+	if _, err := logger.Record(audit.Event{Actor: "user-admin", Action: "config.update", Details: map[string]interface{}{"attempt": 1, "keys": []string{"a", "b"}}}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if _, err := logger.Record(audit.Event{Actor: "git", Action: "git.push", Resource: "main", ProjectID: "proj-1", Outcome: audit.OutcomeFailure}); err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+
+	// A fresh logger continues the persisted chain
+	reopened, err := audit.NewLogger(db)
+	if err != nil {
+		t.Fatalf("NewLogger (reopen) failed: %v", err)
+	}
+	entry, err := reopened.Record(audit.Event{Actor: "plugin-loader", Action: "plugin.load", Resource: "custom"})
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	if entry.Sequence != 3 {
+		t.Errorf("expected sequence 3, got %d", entry.Sequence)
+	}
+
+	result, err := reopened.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !result.Valid || result.Checked != 3 {
+		t.Fatalf("expected valid chain of 3, got %+v", result)
+	}
+
+	gitOps, err := db.QueryAuditEntries(audit.Filter{Action: "git.*"})
+	if err != nil {
+		t.Fatalf("QueryAuditEntries failed: %v", err)
+	}
+	if len(gitOps) != 1 || gitOps[0].ProjectID != "proj-1" {
+		t.Errorf("expected one git entry for proj-1, got %+v", gitOps)
+	}
+
+	if _, err := db.DB().Exec(`UPDATE audit_log SET actor = 'someone-else' WHERE seq = 2`); err != nil {
+		t.Fatalf("tamper failed: %v", err)
+	}
+	result, err = reopened.Verify()
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if result.Valid || result.BrokenAt != 2 {
+		t.Errorf("expected chain broken at 2, got %+v", result)
+	}
+}
+
+func TestAPIKeys_PersistAcrossManagers(t *testing.T) {
+	db := newTestDB(t)
+
+	m := auth.NewManager("test-secret")
+	if err := m.SetAPIKeyStore(db); err != nil {
+		t.Fatalf("SetAPIKeyStore failed: %v", err)
+	}
+	resp, err := m.CreateAPIKey("user-admin", auth.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"analytics:read"}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	revoked, err := m.CreateAPIKey("user-admin", auth.CreateAPIKeyRequest{Name: "old", Permissions: []string{"beads:read"}})
+	if err != nil {
+		t.Fatalf("CreateAPIKey failed: %v", err)
+	}
+	if err := m.RevokeAPIKey(revoked.ID, "user-admin"); err != nil {
+		t.Fatalf("RevokeAPIKey failed: %v", err)
+	}
+
+	// A restarted server accepts the surviving key and rejects the revoked one
+	restarted := auth.NewManager("test-secret")
+	if err := restarted.SetAPIKeyStore(db); err != nil {
+		t.Fatalf("SetAPIKeyStore (restart) failed: %v", err)
+	}
+	userID, perms, err := restarted.ValidateAPIKey(resp.Key)
+	if err != nil {
+		t.Fatalf("ValidateAPIKey after restart failed: %v", err)
+	}
+	if userID != "user-admin" || len(perms) != 1 || perms[0] != "analytics:read" {
+		t.Errorf("unexpected key grants after restart: %s %v", userID, perms)
+	}
+	if _, _, err := restarted.ValidateAPIKey(revoked.Key); err == nil {
+		t.Error("revoked key should stay revoked after restart")
+	}
+
+	stored, err := db.ListAPIKeys()
+	if err != nil {
+		t.Fatalf("ListAPIKeys failed: %v", err)
+	}
+	if len(stored) != 2 || stored[0].LastUsed.IsZero() || stored[1].RevokedAt == nil {
+		t.Errorf("expected last use and revocation to be stored, got %+v %+v", stored[0], stored[1])
+	}
+	for _, k := range stored {
+		if k.KeyHash == "" || k.KeyHash == resp.Key || k.KeyHash == revoked.Key {
+			t.Errorf("key %s should be stored hashed", k.ID)
+		}
+	}
+}
+
+// ============================================================
+// 25. Dispatch snapshots
+// ============================================================
+
+func TestDispatchSnapshots_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+
+	first := &DispatchSnapshot{
+		ID:              "task-bd-1-1",
+		BeadID:          "bd-1",
+		ProjectID:       "proj-1",
+		AgentID:         "agent-1",
+		DispatchNumber:  1,
+		BeadStatus:      "open",
+		BeadContextJSON: `{"priority_hint":"low"}`,
+		Status:          DispatchSnapshotRunning,
+		CreatedAt:       time.Now().Add(-time.Minute),
+	}
+	if err := db.SaveDispatchSnapshot(first); err != nil {
+		t.Fatalf("SaveDispatchSnapshot failed: %v", err)
+	}
+	second := &DispatchSnapshot{ID: "task-bd-1-2", BeadID: "bd-1", ProjectID: "proj-1", DispatchNumber: 2, BeadStatus: "in_progress", Status: DispatchSnapshotRunning}
+	if err := db.SaveDispatchSnapshot(second); err != nil {
+		t.Fatalf("SaveDispatchSnapshot failed: %v", err)
+	}
+
+	// Completing a dispatch records what it changed
+	completed := time.Now()
+	first.CommitSHAs = []string{"aaa", "bbb"}
+	first.Branches = []string{"agent/bd-1/fix"}
+	first.PRNumber = 7
+	first.PRURL = "https://example.com/pull/7"
+	first.Status = DispatchSnapshotCompleted
+	first.CompletedAt = &completed
+	if err := db.SaveDispatchSnapshot(first); err != nil {
+		t.Fatalf("SaveDispatchSnapshot (complete) failed: %v", err)
+	}
+
+	got, err := db.GetDispatchSnapshot("task-bd-1-1")
+	if err != nil {
+		t.Fatalf("GetDispatchSnapshot failed: %v", err)
+	}
+	if got == nil || got.Status != DispatchSnapshotCompleted || got.CompletedAt == nil {
+		t.Fatalf("expected completed snapshot, got %+v", got)
+	}
+	if len(got.CommitSHAs) != 2 || got.CommitSHAs[1] != "bbb" || len(got.Branches) != 1 || got.PRNumber != 7 {
+		t.Errorf("unexpected recorded changes: %+v", got)
+	}
+	if got.BeadContextJSON != `{"priority_hint":"low"}` || got.AgentID != "agent-1" {
+		t.Errorf("unexpected prior bead state: %+v", got)
+	}
+
+	list, err := db.ListDispatchSnapshots("bd-1")
+	if err != nil {
+		t.Fatalf("ListDispatchSnapshots failed: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != "task-bd-1-1" || list[1].ID != "task-bd-1-2" {
+		t.Errorf("expected snapshots oldest first, got %d", len(list))
+	}
+	if list[1].CommitSHAs == nil || len(list[1].CommitSHAs) != 0 {
+		t.Errorf("expected empty commit list, got %v", list[1].CommitSHAs)
+	}
+
+	missing, err := db.GetDispatchSnapshot("nope")
+	if err != nil || missing != nil {
+		t.Errorf("expected nil, nil for a missing snapshot, got %v, %v", missing, err)
+	}
+}
+
+// ============================================================
+// 26. Run artifacts
+// ============================================================
+
+func TestArtifacts_DedupeAndImmutable(t *testing.T) {
+	db := newTestDB(t)
+	rec := artifacts.NewRecorder(db)
+	ref := artifacts.Ref{DispatchID: "task-bd-1-1", BeadID: "bd-1", ProjectID: "proj-1"}
+
+	first, err := rec.Record(ref, artifacts.KindTestLog, "run_tests", 1, []byte("ok  pkg 0.1s"))
+	if err != nil {
+		t.Fatalf("Record failed: %v", err)
+	}
+	second, err := rec.Record(ref, artifacts.KindTestLog, "run_tests", 2, []byte("ok  pkg 0.1s"))
+	if err != nil {
+		t.Fatalf("Record (duplicate content) failed: %v", err)
+	}
+	if first.Digest != second.Digest || first.ID == second.ID {
+		t.Errorf("expected two records sharing one digest, got %+v and %+v", first, second)
+	}
+
+	var blobs int
+	if err := db.DB().QueryRow(`SELECT COUNT(*) FROM artifact_blobs`).Scan(&blobs); err != nil {
+		t.Fatalf("count blobs: %v", err)
+	}
+	if blobs != 1 {
+		t.Errorf("expected identical content stored once, got %d blobs", blobs)
+	}
+
+	list, err := db.ListRunArtifacts("task-bd-1-1")
+	if err != nil {
+		t.Fatalf("ListRunArtifacts failed: %v", err)
+	}
+	if len(list) != 2 || list[0].Iteration != 1 || list[1].BeadID != "bd-1" || list[1].Name != "run_tests" {
+		t.Errorf("unexpected artifacts: %+v", list)
+	}
+
+	if _, err := db.DB().Exec(`UPDATE artifact_blobs SET content = ?`, []byte("tampered")); err == nil {
+		t.Error("expected blob update to be rejected")
+	}
+	if _, err := db.DB().Exec(`DELETE FROM run_artifacts`); err == nil {
+		t.Error("expected artifact delete to be rejected")
+	}
+
+	verify, err := rec.Verify("task-bd-1-1")
+	if err != nil {
+		t.Fatalf("Verify failed: %v", err)
+	}
+	if !verify.Valid || verify.Checked != 1 {
+		t.Errorf("expected one valid blob, got %+v", verify)
+	}
+}
+
+// ============================================================
+// 27. Compliance constraints and provider tags
+// ============================================================
+
+func TestProjectCompliance_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+
+	regulated := &models.Project{
+		ID: "proj-eu", Name: "EU", GitRepo: ".", Branch: "main", BeadsPath: ".beads",
+		Status: models.ProjectStatusOpen,
+		Compliance: &models.ComplianceConstraints{
+			RequiredProviderTags: []string{"eu-hosted"},
+			NoBodyLogging:        true,
+			RequireRedaction:     true,
+		},
+	}
+	open := &models.Project{ID: "proj-open", Name: "Open", GitRepo: ".", Branch: "main", BeadsPath: ".beads", Status: models.ProjectStatusOpen}
+	for _, p := range []*models.Project{regulated, open} {
+		if err := db.UpsertProject(p); err != nil {
+			t.Fatalf("UpsertProject failed: %v", err)
+		}
+	}
+
+	projects, err := db.ListProjects()
+	if err != nil {
+		t.Fatalf("ListProjects failed: %v", err)
+	}
+	byID := map[string]*models.Project{}
+	for _, p := range projects {
+		byID[p.ID] = p
+	}
+	c := byID["proj-eu"].Compliance
+	if c == nil || !c.NoBodyLogging || !c.RequireRedaction || len(c.RequiredProviderTags) != 1 {
+		t.Errorf("expected compliance constraints to round-trip, got %+v", c)
+	}
+	if byID["proj-open"].Compliance != nil {
+		t.Errorf("expected no constraints on the open project, got %+v", byID["proj-open"].Compliance)
+	}
+
+	if err := db.UpsertProvider(&internalmodels.Provider{ID: "eu-1", Name: "EU", Type: "openai", Endpoint: "http://x", Status: "active", Tags: []string{"eu-hosted", "gdpr"}}); err != nil {
+		t.Fatalf("UpsertProvider failed: %v", err)
+	}
+	got, err := db.GetProvider("eu-1")
+	if err != nil {
+		t.Fatalf("GetProvider failed: %v", err)
+	}
+	if len(got.Tags) != 2 || got.Tags[0] != "eu-hosted" {
+		t.Errorf("expected provider tags to round-trip, got %v", got.Tags)
+	}
+	list, err := db.ListProviders()
+	if err != nil || len(list) != 1 || len(list[0].Tags) != 2 {
+		t.Errorf("expected ListProviders to include tags, got %+v (%v)", list, err)
+	}
+}
+
+// ============================================================
+// 28. Event webhooks
+// ============================================================
+
+func TestEventWebhooks_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	sub := &eventhooks.Subscription{
+		ID: "wh-1", Name: "ci", URL: "https://ci.example.com/hook", Secret: "s3cret",
+		EventTypes: []string{"bead.completed", "workflow.*"}, ProjectIDs: []string{"proj-1"},
+		Enabled: true, CreatedBy: "admin", CreatedAt: now, UpdatedAt: now,
+	}
+	if err := db.SaveEventWebhook(sub); err != nil {
+		t.Fatalf("SaveEventWebhook failed: %v", err)
+	}
+	sub.Enabled = false
+	if err := db.SaveEventWebhook(sub); err != nil {
+		t.Fatalf("SaveEventWebhook (update) failed: %v", err)
+	}
+	got, err := db.GetEventWebhook("wh-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetEventWebhook failed: %v", err)
+	}
+	if got.Enabled || got.Secret != "s3cret" || len(got.EventTypes) != 2 || got.ProjectIDs[0] != "proj-1" {
+		t.Errorf("unexpected subscription: %+v", got)
+	}
+
+	for i, status := range []string{eventhooks.StatusDelivered, eventhooks.StatusPending} {
+		next := now.Add(time.Minute)
+		d := &eventhooks.Delivery{
+			ID: fmt.Sprintf("del-%d", i), SubscriptionID: "wh-1", EventID: "ev", EventType: "bead.completed",
+			Payload: `{}`, Status: status, Attempts: 1, ResponseCode: 503, Error: "unavailable",
+			CreatedAt: now.Add(time.Duration(i) * time.Second), UpdatedAt: now,
+		}
+		if status == eventhooks.StatusPending {
+			d.NextAttemptAt = &next
+		}
+		if err := db.SaveWebhookDelivery(d); err != nil {
+			t.Fatalf("SaveWebhookDelivery failed: %v", err)
+		}
+	}
+
+	pending, total, err := db.ListWebhookDeliveries(eventhooks.DeliveryFilter{Status: eventhooks.StatusPending})
+	if err != nil || total != 1 || pending[0].ID != "del-1" || pending[0].NextAttemptAt == nil {
+		t.Errorf("expected one pending delivery with a retry time, got %+v (%d, %v)", pending, total, err)
+	}
+	page, total, err := db.ListWebhookDeliveries(eventhooks.DeliveryFilter{SubscriptionID: "wh-1", Limit: 1})
+	if err != nil || total != 2 || len(page) != 1 || page[0].ID != "del-1" {
+		t.Errorf("expected newest delivery first of 2, got %+v (%d, %v)", page, total, err)
+	}
+
+	if err := db.DeleteEventWebhook("wh-1"); err != nil {
+		t.Fatalf("DeleteEventWebhook failed: %v", err)
+	}
+	if got, _ := db.GetEventWebhook("wh-1"); got != nil {
+		t.Error("expected subscription to be deleted")
+	}
+	if _, total, _ := db.ListWebhookDeliveries(eventhooks.DeliveryFilter{}); total != 0 {
+		t.Errorf("expected delivery log to be deleted with its subscription, got %d", total)
+	}
+}
+
+// ============================================================
+// 29. Report schedules
+// ============================================================
+
+func TestReportSchedules_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+	next := now.Add(time.Hour)
+
+	s := &reports.Schedule{
+		ID: "rs-1", OrgID: "acme", Name: "weekly cost", ReportType: reports.ReportCost,
+		Format: reports.FormatCSV, Params: map[string]string{"project_id": "proj-1"},
+		Frequency: reports.FrequencyWeekly, At: "09:00", Weekday: 1, Timezone: "Europe/Berlin",
+		Destinations: []reports.Destination{{Type: reports.DestinationEmail, To: []string{"ops@example.com"}}},
+		Enabled:      true, NextRunAt: &next, CreatedBy: "admin", CreatedAt: now, UpdatedAt: now,
+	}
+	if err := db.SaveReportSchedule(s); err != nil {
+		t.Fatalf("SaveReportSchedule failed: %v", err)
+	}
+	s.LastRunAt = &now
+	s.LastStatus = reports.RunPartial
+	if err := db.SaveReportSchedule(s); err != nil {
+		t.Fatalf("SaveReportSchedule (update) failed: %v", err)
+	}
+	got, err := db.GetReportSchedule("rs-1")
+	if err != nil || got == nil {
+		t.Fatalf("GetReportSchedule failed: %v", err)
+	}
+	if got.Params["project_id"] != "proj-1" || got.Destinations[0].To[0] != "ops@example.com" ||
+		got.LastStatus != reports.RunPartial || got.NextRunAt == nil || got.LastRunAt == nil {
+		t.Errorf("unexpected schedule: %+v", got)
+	}
+	if list, _ := db.ListReportSchedules("other"); len(list) != 0 {
+		t.Errorf("expected no schedules for another org, got %d", len(list))
+	}
+	if list, _ := db.ListReportSchedules("acme"); len(list) != 1 {
+		t.Errorf("expected one schedule for acme, got %d", len(list))
+	}
+
+	for i, status := range []string{reports.RunSucceeded, reports.RunFailed} {
+		r := &reports.Run{
+			ID: fmt.Sprintf("run-%d", i), ScheduleID: "rs-1", OrgID: "acme", ReportType: reports.ReportCost,
+			Format: reports.FormatCSV, Trigger: reports.TriggerManual, Status: status,
+			PeriodStart: now.Add(-24 * time.Hour), PeriodEnd: now, Filename: "cost.csv", SizeBytes: 42,
+			Destinations: []reports.DestinationResult{{Type: reports.DestinationEmail, Target: "ops@example.com"}},
+			StartedAt:    now.Add(time.Duration(i) * time.Second), FinishedAt: now,
+		}
+		if err := db.SaveReportRun(r); err != nil {
+			t.Fatalf("SaveReportRun failed: %v", err)
+		}
+	}
+	failed, total, err := db.ListReportRuns(reports.RunFilter{Status: reports.RunFailed})
+	if err != nil || total != 1 || failed[0].ID != "run-1" || len(failed[0].Destinations) != 1 {
+		t.Errorf("expected one failed run, got %+v (%d, %v)", failed, total, err)
+	}
+	page, total, err := db.ListReportRuns(reports.RunFilter{ScheduleID: "rs-1", Limit: 1})
+	if err != nil || total != 2 || len(page) != 1 || page[0].ID != "run-1" {
+		t.Errorf("expected newest run first of 2, got %+v (%d, %v)", page, total, err)
+	}
+
+	if err := db.DeleteReportSchedule("rs-1"); err != nil {
+		t.Fatalf("DeleteReportSchedule failed: %v", err)
+	}
+	if got, _ := db.GetReportSchedule("rs-1"); got != nil {
+		t.Error("expected schedule to be deleted")
+	}
+	if _, total, _ := db.ListReportRuns(reports.RunFilter{}); total != 0 {
+		t.Errorf("expected runs to be deleted with their schedule, got %d", total)
+	}
+}
+
+// ============================================================
+// 30. Policy versions
+// ============================================================
+
+func TestPolicyVersions_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	if got, err := db.GetPolicyVersion("proj-1", 0); err != nil || got != nil {
+		t.Fatalf("expected no policy before publishing, got %+v (%v)", got, err)
+	}
+	for i := 1; i <= 2; i++ {
+		v := &policy.Version{
+			ProjectID: "proj-1", Version: i, Comment: fmt.Sprintf("v%d", i), CreatedBy: "admin", CreatedAt: now,
+			Document: policy.Document{Rules: []policy.Rule{{
+				ID: "no-push", Kind: policy.KindToolPermission, Effect: policy.EffectDeny,
+				When: []policy.Condition{{Field: "action", Op: policy.OpEq, Value: "git_push"}},
+			}}},
+		}
+		if err := db.SavePolicyVersion(v); err != nil {
+			t.Fatalf("SavePolicyVersion failed: %v", err)
+		}
+	}
+	if err := db.SavePolicyVersion(&policy.Version{ProjectID: "proj-1", Version: 2, CreatedAt: now}); err == nil {
+		t.Error("expected saving a taken version number to fail")
+	}
+
+	latest, err := db.GetPolicyVersion("proj-1", 0)
+	if err != nil || latest == nil || latest.Version != 2 || latest.Comment != "v2" {
+		t.Fatalf("expected latest version 2, got %+v (%v)", latest, err)
+	}
+	if latest.Document.Rules[0].When[0].Value != "git_push" {
+		t.Errorf("document not round-tripped: %+v", latest.Document)
+	}
+	first, err := db.GetPolicyVersion("proj-1", 1)
+	if err != nil || first == nil || first.Version != 1 {
+		t.Errorf("expected version 1, got %+v (%v)", first, err)
+	}
+	list, err := db.ListPolicyVersions("proj-1")
+	if err != nil || len(list) != 2 || list[0].Version != 2 {
+		t.Errorf("expected 2 versions newest first, got %+v (%v)", list, err)
+	}
+	if list, _ := db.ListPolicyVersions("proj-2"); len(list) != 0 {
+		t.Errorf("expected no versions for another project, got %d", len(list))
+	}
+}
+
+// ============================================================
+// 31. Postgres backend
+// ============================================================
+
+func TestRebind(t *testing.T) {
+	query := "SELECT * FROM t WHERE a = ? AND b IN (?, ?) AND c = 'why?' LIMIT ?"
+
+	sqlite := &Database{dbType: "sqlite"}
+	if got := sqlite.rebind(query); got != query {
+		t.Errorf("sqlite rebind changed the query: %s", got)
+	}
+
+	pg := &Database{dbType: "postgres"}
+	want := "SELECT * FROM t WHERE a = $1 AND b IN ($2, $3) AND c = 'why?' LIMIT $4"
+	if got := pg.rebind(query); got != want {
+		t.Errorf("postgres rebind = %s, want %s", got, want)
+	}
+}
+
+// TestPostgres_RoundTrip runs the shared tables against a real server when
+// LOOM_TEST_POSTGRES_DSN points at a scratch database
+func TestPostgres_RoundTrip(t *testing.T) {
+	dsn := os.Getenv("LOOM_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("LOOM_TEST_POSTGRES_DSN not set")
+	}
+	db, err := NewPostgres(dsn, PoolConfig{MaxOpenConns: 4})
+	if err != nil {
+		t.Fatalf("NewPostgres: %v", err)
+	}
+	defer db.Close()
+	if db.DB().Stats().MaxOpenConnections != 4 {
+		t.Errorf("pool size not applied: %d", db.DB().Stats().MaxOpenConnections)
+	}
+
+	suffix := fmt.Sprint(time.Now().UnixNano())
+	userID := "user-pg-" + suffix
+	if err := db.CreateUser(userID, "pg-"+suffix, "pg@example.com", "viewer"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	users, err := db.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	found := false
+	for _, u := range users {
+		found = found || u.ID == userID
+	}
+	if !found {
+		t.Error("created user not listed")
+	}
+
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	activity := &Activity{
+		ID: "act-" + suffix, EventType: "bead.created", Timestamp: now, Source: "test",
+		ProjectID: "proj-pg", Action: "created", ResourceType: "bead", ResourceID: "b-1",
+		AggregationKey: "agg-" + suffix, AggregationCount: 1, IsAggregated: true, Visibility: "project",
+	}
+	if err := db.CreateActivity(activity); err != nil {
+		t.Fatalf("CreateActivity: %v", err)
+	}
+	got, err := db.GetRecentAggregatableActivity("agg-"+suffix, now.Add(-time.Minute))
+	if err != nil || got == nil || got.ID != activity.ID {
+		t.Fatalf("GetRecentAggregatableActivity = %v, %v", got, err)
+	}
+	if n, err := db.CountActivities(ActivityFilters{ProjectIDs: []string{"proj-pg"}, Since: now.Add(-time.Minute)}); err != nil || n < 1 {
+		t.Errorf("CountActivities = %d, %v", n, err)
+	}
+
+	if err := db.CreateNotification(&Notification{
+		ID: "notif-" + suffix, UserID: userID, ActivityID: activity.ID, EventType: "bead.created",
+		Title: "t", Message: "m", Status: "unread", Priority: "high", CreatedAt: now,
+	}); err != nil {
+		t.Fatalf("CreateNotification: %v", err)
+	}
+	if _, err := db.MarkAllNotificationsRead(userID); err != nil {
+		t.Fatalf("MarkAllNotificationsRead: %v", err)
+	}
+	notifs, err := db.QueryNotifications(userID, NotificationFilters{SortBy: "priority"})
+	if err != nil || len(notifs) != 1 || notifs[0].ReadAt == nil {
+		t.Fatalf("QueryNotifications = %v, %v", notifs, err)
+	}
+
+	prefs := &NotificationPreferences{ID: "prefs-" + suffix, UserID: userID, EnableInApp: true,
+		DigestMode: "realtime", QuietHoursStart: "22:00", MinPriority: "normal", UpdatedAt: now}
+	for i := 0; i < 2; i++ {
+		if err := db.UpsertNotificationPreferences(prefs); err != nil {
+			t.Fatalf("UpsertNotificationPreferences: %v", err)
+		}
+	}
+	if p, err := db.GetNotificationPreferences(userID); err != nil || p == nil || p.QuietHoursStart != "22:00" {
+		t.Errorf("GetNotificationPreferences = %+v, %v", p, err)
+	}
+
+	lesson := &models.Lesson{ID: "lesson-" + suffix, ProjectID: "proj-pg-" + suffix, Category: "build",
+		Title: "t", Detail: "d", SourceBeadID: "b-1", SourceAgentID: "a-1"}
+	if err := db.StoreLessonWithEmbedding(lesson, []float32{1, 0}); err != nil {
+		t.Fatalf("StoreLessonWithEmbedding: %v", err)
+	}
+	lessons, err := db.SearchLessonsBySimilarity(lesson.ProjectID, []float32{1, 0}, 5)
+	if err != nil || len(lessons) != 1 {
+		t.Errorf("SearchLessonsBySimilarity = %v, %v", lessons, err)
+	}
+}
+
+// ============================================================
+// 32. Golden prompts
+// ============================================================
+
+func TestGoldenPrompts_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	c := &goldenprompts.Case{
+		ID: "gp-1", ProjectID: "proj-1", Name: "json status", Prompt: "Status as JSON",
+		Expectations: []goldenprompts.Expectation{{Type: goldenprompts.ExpectJSON}},
+		ProviderIDs:  []string{"p1"}, Enabled: true, CreatedAt: now, UpdatedAt: now,
+	}
+	if err := db.SaveGoldenCase(c); err != nil {
+		t.Fatalf("SaveGoldenCase failed: %v", err)
+	}
+	got, err := db.GetGoldenCase("gp-1")
+	if err != nil || got == nil || got.Name != "json status" || !got.Enabled || len(got.ProviderIDs) != 1 || got.Expectations[0].Type != goldenprompts.ExpectJSON {
+		t.Fatalf("case not round-tripped: %+v (%v)", got, err)
+	}
+	c.Enabled = false
+	if err := db.SaveGoldenCase(c); err != nil {
+		t.Fatalf("SaveGoldenCase (update) failed: %v", err)
+	}
+	if list, err := db.ListGoldenCases(""); err != nil || len(list) != 1 || list[0].Enabled {
+		t.Errorf("expected one disabled case, got %+v (%v)", list, err)
+	}
+
+	for i := 0; i < 3; i++ {
+		r := &goldenprompts.Run{
+			ID: fmt.Sprintf("run-%d", i), ProjectID: "proj-1", Trigger: goldenprompts.TriggerManual,
+			Status: goldenprompts.RunPassed, Cases: 1, Passed: 1,
+			Results:   []goldenprompts.Result{{CaseID: "gp-1", ProviderID: "p1", Passed: true, Score: 1}},
+			StartedAt: now.Add(time.Duration(i) * time.Minute), FinishedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.SaveGoldenRun(r); err != nil {
+			t.Fatalf("SaveGoldenRun failed: %v", err)
+		}
+	}
+	runs, err := db.ListGoldenRuns("proj-1", 2)
+	if err != nil || len(runs) != 2 || runs[0].ID != "run-2" || runs[0].Results[0].Score != 1 {
+		t.Fatalf("expected 2 runs newest first, got %+v (%v)", runs, err)
+	}
+	if r, err := db.GetGoldenRun("missing"); err != nil || r != nil {
+		t.Errorf("expected no run, got %+v (%v)", r, err)
+	}
+
+	if err := db.DeleteGoldenCase("gp-1"); err != nil {
+		t.Fatalf("DeleteGoldenCase failed: %v", err)
+	}
+	if got, _ := db.GetGoldenCase("gp-1"); got != nil {
+		t.Error("expected case to be deleted")
+	}
+}
+
+func TestProjectTemplates_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	tmpl := &projecttemplates.Template{
+		ID: "tmpl-1", OrgID: "acme", Name: "Go service", GitRepo: "git@github.com:acme/svc.git", Branch: "main", BeadsPath: ".beads",
+		Personas:      []string{"default/qa-engineer"},
+		Providers:     map[string]string{projecttemplates.AnyRole: "p1"},
+		Beads:         []projecttemplates.SeedBead{{Title: "Set up CI", Priority: 1, Type: "task"}},
+		Notifications: []projecttemplates.NotificationRule{{URL: "https://hooks.example.com/x", EventTypes: []string{"bead.*"}}},
+		CreatedAt:     now, UpdatedAt: now,
+	}
+	if err := db.SaveProjectTemplate(tmpl); err != nil {
+		t.Fatalf("SaveProjectTemplate failed: %v", err)
+	}
+	got, err := db.GetProjectTemplate("tmpl-1")
+	if err != nil || got == nil || got.OrgID != "acme" || got.ProviderFor("qa-engineer") != "p1" || got.Beads[0].Title != "Set up CI" || got.Notifications[0].EventTypes[0] != "bead.*" {
+		t.Fatalf("template not round-tripped: %+v (%v)", got, err)
+	}
+	tmpl.Name = "Go microservice"
+	if err := db.SaveProjectTemplate(tmpl); err != nil {
+		t.Fatalf("SaveProjectTemplate (update) failed: %v", err)
+	}
+	if list, err := db.ListProjectTemplates(); err != nil || len(list) != 1 || list[0].Name != "Go microservice" {
+		t.Errorf("expected one renamed template, got %+v (%v)", list, err)
+	}
+
+	if err := db.DeleteProjectTemplate("tmpl-1"); err != nil {
+		t.Fatalf("DeleteProjectTemplate failed: %v", err)
+	}
+	if got, err := db.GetProjectTemplate("tmpl-1"); err != nil || got != nil {
+		t.Errorf("expected template to be deleted, got %+v (%v)", got, err)
+	}
+}
+
+func TestPersonaVersions_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, prompt := range []string{"Review carefully.", "Review quickly."} {
+		v := &persona.Version{
+			Name: "default/code-reviewer", Version: i + 1, CreatedAt: now, CreatedBy: "admin",
+			Definition: persona.Definition{SystemPrompt: prompt, AllowedActions: []string{"read_file"}, ModelTier: persona.TierLarge},
+		}
+		if err := db.SavePersonaVersion(v); err != nil {
+			t.Fatalf("SavePersonaVersion v%d failed: %v", v.Version, err)
+		}
+	}
+	if err := db.SavePersonaVersion(&persona.Version{Name: "default/code-reviewer", Version: 2, CreatedAt: now}); err == nil {
+		t.Error("expected a taken version number to be rejected")
+	}
+	if err := db.SavePersonaVersion(&persona.Version{Name: "default/code-reviewer", Version: 3, RolledBackFrom: 1, CreatedAt: now,
+		Definition: persona.Definition{SystemPrompt: "Review carefully."}}); err != nil {
+		t.Fatalf("SavePersonaVersion v3 failed: %v", err)
+	}
+
+	latest, err := db.GetPersonaVersion("default/code-reviewer", 0)
+	if err != nil || latest == nil || latest.Version != 3 || latest.RolledBackFrom != 1 {
+		t.Fatalf("expected v3 rolled back from v1, got %+v (%v)", latest, err)
+	}
+	v1, err := db.GetPersonaVersion("default/code-reviewer", 1)
+	if err != nil || v1 == nil || v1.Definition.ModelTier != persona.TierLarge || v1.Definition.AllowedActions[0] != "read_file" || v1.CreatedBy != "admin" {
+		t.Fatalf("v1 not round-tripped: %+v (%v)", v1, err)
+	}
+	if list, err := db.ListPersonaVersions("default/code-reviewer"); err != nil || len(list) != 3 || list[0].Version != 3 {
+		t.Errorf("expected three versions newest first, got %+v (%v)", list, err)
+	}
+	if names, err := db.ListVersionedPersonas(); err != nil || len(names) != 1 || names[0] != "default/code-reviewer" {
+		t.Errorf("expected one versioned persona, got %v (%v)", names, err)
+	}
+
+	if err := db.DeletePersonaVersions("default/code-reviewer"); err != nil {
+		t.Fatalf("DeletePersonaVersions failed: %v", err)
+	}
+	if got, err := db.GetPersonaVersion("default/code-reviewer", 0); err != nil || got != nil {
+		t.Errorf("expected versions to be deleted, got %+v (%v)", got, err)
+	}
+}
+
+// ============================================================
+// 33. Schema migrations
+// ============================================================
+
+func TestMigrations_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	ctx := context.Background()
+	m, err := db.Migrator()
+	if err != nil {
+		t.Fatalf("Migrator failed: %v", err)
+	}
+	if v, dirty, err := m.Version(ctx); err != nil || v != m.Latest() || dirty {
+		t.Fatalf("Version = %d (dirty %v, %v), want %d", v, dirty, err, m.Latest())
+	}
+
+	// Every migration reverts cleanly and reapplies
+	if _, err := m.To(ctx, 0); err != nil {
+		t.Fatalf("To(0) failed: %v", err)
+	}
+	var tables int
+	if err := db.DB().QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name NOT IN ('schema_migrations', 'sqlite_sequence')").Scan(&tables); err != nil || tables != 0 {
+		t.Fatalf("expected no tables after reverting everything, got %d (%v)", tables, err)
+	}
+	if err := db.Migrate(ctx); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if users, err := db.ListUsers(); err != nil || len(users) != 1 || users[0].Username != "admin" {
+		t.Errorf("default admin user not recreated: %+v (%v)", users, err)
+	}
+}
+
+func TestMigrations_AdoptLegacyDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	raw, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A providers table from before the routing and ownership columns
+	_, err = raw.Exec(`CREATE TABLE providers (
+		id TEXT PRIMARY KEY, name TEXT NOT NULL, type TEXT NOT NULL, endpoint TEXT NOT NULL,
+		description TEXT, requires_key BOOLEAN NOT NULL DEFAULT 0, key_id TEXT,
+		status TEXT NOT NULL DEFAULT 'active', metrics_json TEXT, gpu_constraints_json TEXT,
+		created_at DATETIME NOT NULL, updated_at DATETIME NOT NULL)`)
+	if err == nil {
+		_, err = raw.Exec(`INSERT INTO providers (id, name, type, endpoint, created_at, updated_at)
+			VALUES ('p1', 'old', 'openai', 'http://localhost', datetime('now'), datetime('now'))`)
+	}
+	raw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := New(path)
+	if err != nil {
+		t.Fatalf("New on legacy database failed: %v", err)
+	}
+	defer db.Close()
+	var contextWindow int
+	var streaming, shared bool
+	var schemaVersion string
+	err = db.DB().QueryRow("SELECT context_window, supports_streaming, is_shared, schema_version FROM providers WHERE id = 'p1'").
+		Scan(&contextWindow, &streaming, &shared, &schemaVersion)
+	if err != nil {
+		t.Fatalf("legacy provider columns not added: %v", err)
+	}
+	if contextWindow != 4096 || !streaming || !shared || schemaVersion != "1.0" {
+		t.Errorf("legacy provider not adopted: context_window=%d streaming=%v shared=%v schema_version=%q", contextWindow, streaming, shared, schemaVersion)
+	}
+}
+
+func TestMigrations_DirtyDatabaseRefused(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dirty.db")
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.DB().Exec("UPDATE schema_migrations SET dirty = 1 WHERE version = (SELECT MAX(version) FROM schema_migrations)")
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New(path); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Fatalf("expected dirty database to be refused, got %v", err)
+	}
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, _ := db.Migrator()
+	if err := m.Force(context.Background(), m.Latest()); err != nil {
+		t.Fatalf("Force failed: %v", err)
+	}
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Errorf("Migrate after force failed: %v", err)
+	}
+}
+
+// ============================================================
+// 34. Backups
+// ============================================================
+
+func TestBackup_DumpVerifyRestore(t *testing.T) {
+	dir := t.TempDir()
+	db, err := New(filepath.Join(dir, "live.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	countProviders := func() int {
+		var n int
+		if err := db.DB().QueryRow("SELECT COUNT(*) FROM providers").Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	addProvider := func(id string) {
+		_, err := db.DB().Exec(`INSERT INTO providers (id, name, type, endpoint, created_at, updated_at)
+			VALUES (?, 'p', 'openai', 'http://localhost', datetime('now'), datetime('now'))`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	addProvider("p1")
+
+	dump := filepath.Join(dir, "backup.sqlite")
+	if err := db.Dump(ctx, dump); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	latest, _ := db.LatestSchemaVersion()
+	if version, err := db.VerifyDump(ctx, dump); err != nil || version != latest {
+		t.Fatalf("VerifyDump = %d, %v; want %d", version, err, latest)
+	}
+	if version, err := db.SchemaVersion(ctx); err != nil || version != latest {
+		t.Fatalf("SchemaVersion = %d, %v; want %d", version, err, latest)
+	}
+
+	addProvider("p2")
+	if err := db.Restore(ctx, dump); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if n := countProviders(); n != 1 {
+		t.Errorf("expected 1 provider after restore, got %d", n)
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.sqlite")
+	if err := os.WriteFile(corrupt, []byte("not a database"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.VerifyDump(ctx, corrupt); err == nil {
+		t.Error("expected VerifyDump to reject a corrupt file")
+	}
+	if err := db.Restore(ctx, filepath.Join(dir, "missing.sqlite")); err == nil {
+		t.Error("expected Restore of a missing file to fail")
+	}
+}
+
+// ============================================================
+// 35. Scheduler timers
+// ============================================================
+
+func TestSchedulerTimers_ClaimAndFinish(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC()
+
+	timer := &scheduler.Timer{
+		ID:        "loom-heartbeat-master",
+		Activity:  "LoomHeartbeatActivity",
+		Input:     []byte(`{"interval":"10s"}`),
+		Interval:  10 * time.Second,
+		FireAt:    now.Add(-time.Millisecond),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := db.SaveSchedulerTimer(timer); err != nil {
+		t.Fatalf("SaveSchedulerTimer: %v", err)
+	}
+	got, err := db.GetSchedulerTimer(timer.ID)
+	if err != nil || got == nil || got.Interval != 10*time.Second || string(got.Input) != `{"interval":"10s"}` {
+		t.Fatalf("GetSchedulerTimer = %+v, %v", got, err)
+	}
+
+	if ok, err := db.ClaimSchedulerTimer(timer.ID, "a", now, now.Add(time.Minute)); err != nil || !ok {
+		t.Fatalf("first claim = %v, %v", ok, err)
+	}
+	if ok, _ := db.ClaimSchedulerTimer(timer.ID, "b", now, now.Add(time.Minute)); ok {
+		t.Fatal("a leased timer was claimed twice")
+	}
+	// The lease has expired a minute later
+	later := now.Add(time.Minute + time.Second)
+	if ok, _ := db.ClaimSchedulerTimer(timer.ID, "b", later, later.Add(time.Minute)); !ok {
+		t.Fatal("an expired lease was not taken over")
+	}
+
+	got.Runs = 1
+	got.FireAt = later.Add(10 * time.Second)
+	if ok, _ := db.FinishSchedulerTimer(got, "a"); ok {
+		t.Fatal("a lost lease finished the timer")
+	}
+	if ok, err := db.FinishSchedulerTimer(got, "b"); err != nil || !ok {
+		t.Fatalf("FinishSchedulerTimer = %v, %v", ok, err)
+	}
+	if ok, _ := db.ClaimSchedulerTimer(timer.ID, "c", later, later.Add(time.Minute)); ok {
+		t.Fatal("a timer was claimed before it was due")
+	}
+
+	list, err := db.ListSchedulerTimers()
+	if err != nil || len(list) != 1 || list[0].Runs != 1 || list[0].LeaseOwner != "" {
+		t.Fatalf("ListSchedulerTimers = %+v, %v", list, err)
+	}
+	if err := db.DeleteSchedulerTimer(timer.ID); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := db.GetSchedulerTimer(timer.ID); got != nil {
+		t.Error("timer not deleted")
+	}
+}
+
+func TestOrganizations_ScopeProjectsProvidersAndActivity(t *testing.T) {
+	db := newTestDB(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	if err := db.UpsertOrganization(&org.Organization{ID: "acme", Name: "Acme", DailyBudgetUSD: 5, CreatedAt: now, UpdatedAt: now}); err != nil {
+		t.Fatalf("UpsertOrganization failed: %v", err)
+	}
+	orgs, err := db.ListOrganizations()
+	if err != nil || len(orgs) != 1 || orgs[0].DailyBudgetUSD != 5 {
+		t.Fatalf("ListOrganizations = %+v, %v", orgs, err)
+	}
+
+	acmeProject := makeTestProject("proj-acme", "Acme")
+	acmeProject.OrgID = "acme"
+	for _, p := range []*models.Project{acmeProject, makeTestProject("proj-default", "Default")} {
+		if err := db.UpsertProject(p); err != nil {
+			t.Fatalf("UpsertProject failed: %v", err)
+		}
+	}
+	projects, err := db.ListProjectsInOrg("acme")
+	if err != nil || len(projects) != 1 || projects[0].ID != "proj-acme" || projects[0].OrgID != "acme" {
+		t.Fatalf("ListProjectsInOrg(acme) = %+v, %v", projects, err)
+	}
+	if projects, _ := db.ListProjectsInOrg(""); len(projects) != 1 || projects[0].ID != "proj-default" {
+		t.Errorf("ListProjectsInOrg(default) = %+v", projects)
+	}
+
+	reserved := makeTestProvider("prov-acme", "Acme only")
+	reserved.OrgID = "acme"
+	for _, p := range []*internalmodels.Provider{reserved, makeTestProvider("prov-shared", "Shared")} {
+		if err := db.UpsertProvider(p); err != nil {
+			t.Fatalf("UpsertProvider failed: %v", err)
+		}
+	}
+	if providers, _ := db.ListProvidersInOrg("acme"); len(providers) != 2 {
+		t.Errorf("acme should see its own and the shared provider, got %d", len(providers))
+	}
+	if providers, _ := db.ListProvidersInOrg("globex"); len(providers) != 1 || providers[0].ID != "prov-shared" {
+		t.Errorf("globex should see only the shared provider, got %+v", providers)
+	}
+
+	acmeActivity := makeTestActivity("act-acme")
+	acmeActivity.ProjectID = "proj-acme"
+	acmeActivity.OrgID = "acme"
+	defaultActivity := makeTestActivity("act-default")
+	defaultActivity.ProjectID = "proj-default"
+	for _, a := range []*Activity{acmeActivity, defaultActivity} {
+		if err := db.CreateActivity(a); err != nil {
+			t.Fatalf("CreateActivity failed: %v", err)
+		}
+	}
+	results, err := db.ListActivities(ActivityFilters{OrgID: "acme", Limit: 10})
+	if err != nil || len(results) != 1 || results[0].ID != "act-acme" {
+		t.Fatalf("ListActivities(acme) = %+v, %v", results, err)
+	}
+	if n, _ := db.CountActivities(ActivityFilters{OrgID: org.DefaultID}); n != 1 {
+		t.Errorf("expected one default organization activity, got %d", n)
+	}
+}
+
+func TestPRReviews_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, status := range []string{prreview.StatusChangesRequested, prreview.StatusClean} {
+		r := &prreview.Review{
+			ID: fmt.Sprintf("prr-%d", i+1), ProjectID: "proj-1", BeadID: "bd-1", PRNumber: 4, Branch: "agent/bd-1",
+			Base: "main", Persona: "default/code-reviewer", ProviderID: "p1", Status: status,
+			Findings:  []prreview.Finding{{Severity: prreview.SeverityBlocking, Path: "main.go", Line: 3, Message: "nil dereference"}},
+			Blocking:  1, StartedAt: now.Add(time.Duration(i) * time.Minute), FinishedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if status == prreview.StatusClean {
+			r.Findings, r.Blocking, r.Merged = []prreview.Finding{}, 0, true
+		}
+		if err := db.SavePRReview(r); err != nil {
+			t.Fatalf("SavePRReview failed: %v", err)
+		}
+	}
+
+	got, err := db.GetPRReview("prr-1")
+	if err != nil || got == nil || got.Blocking != 1 || len(got.Findings) != 1 || got.Findings[0].Line != 3 || got.BeadID != "bd-1" {
+		t.Fatalf("review not round-tripped: %+v (%v)", got, err)
+	}
+	if missing, err := db.GetPRReview("nope"); missing != nil || err != nil {
+		t.Errorf("GetPRReview(unknown) = %+v, %v", missing, err)
+	}
+	list, err := db.ListPRReviews("proj-1", 1)
+	if err != nil || len(list) != 1 || list[0].ID != "prr-2" || !list[0].Merged {
+		t.Fatalf("ListPRReviews = %+v, %v", list, err)
+	}
+}
+
+func TestCIStatuses_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, branch := range []string{"agent/bd-1/login", "agent/bd-2/logout"} {
+		s := &cistatus.Status{
+			ProjectID: "proj-1", Branch: branch, BeadID: fmt.Sprintf("bd-%d", i+1), SHA: "a1", State: cistatus.StateFailure,
+			Checks:    []cistatus.Check{{Name: "test", State: cistatus.StateFailure, URL: "https://ci.example/1"}},
+			Failures:  []cistatus.Failure{{SHA: "a1", Checks: []string{"test"}, At: now}},
+			UpdatedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.SaveCIStatus(s); err != nil {
+			t.Fatalf("SaveCIStatus failed: %v", err)
+		}
+	}
+
+	got, err := db.GetCIStatus("proj-1", "agent/bd-1/login")
+	if err != nil || got == nil || got.BeadID != "bd-1" || len(got.Checks) != 1 || got.Checks[0].URL == "" || len(got.Failures) != 1 {
+		t.Fatalf("status not round-tripped: %+v (%v)", got, err)
+	}
+	if missing, err := db.GetCIStatus("proj-2", "agent/bd-1/login"); missing != nil || err != nil {
+		t.Errorf("GetCIStatus(unknown) = %+v, %v", missing, err)
+	}
+	list, err := db.ListCIStatuses("")
+	if err != nil || len(list) != 2 || list[0].Branch != "agent/bd-2/logout" {
+		t.Fatalf("ListCIStatuses = %+v, %v", list, err)
+	}
+	if list, _ := db.ListCIStatuses("proj-2"); len(list) != 0 {
+		t.Errorf("expected no statuses in another project, got %d", len(list))
+	}
+}
+
+func TestIssueLinks_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, key := range []string{"OPS-1", "OPS-2"} {
+		l := &issuesync.Link{
+			ProjectID: "proj-1", Tracker: issuesync.TrackerJira, ExternalID: key, BeadID: fmt.Sprintf("bd-%d", i+1),
+			URL: "https://jira.example/browse/" + key, IssueState: issuesync.StateOpen,
+			IssueUpdatedAt: now, SyncedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.SaveIssueLink(l); err != nil {
+			t.Fatalf("SaveIssueLink failed: %v", err)
+		}
+	}
+
+	got, err := db.GetIssueLink("proj-1", issuesync.TrackerJira, "OPS-1")
+	if err != nil || got == nil || got.BeadID != "bd-1" || got.URL == "" || !got.IssueUpdatedAt.Equal(now) {
+		t.Fatalf("link not round-tripped: %+v (%v)", got, err)
+	}
+	got.IssueState = issuesync.StateClosed
+	if err := db.SaveIssueLink(got); err != nil {
+		t.Fatalf("SaveIssueLink failed: %v", err)
+	}
+	if byBead, err := db.GetIssueLinkByBead("bd-1"); err != nil || byBead == nil || byBead.IssueState != issuesync.StateClosed {
+		t.Errorf("GetIssueLinkByBead = %+v, %v", byBead, err)
+	}
+	if missing, err := db.GetIssueLink("proj-1", issuesync.TrackerGitHub, "OPS-1"); missing != nil || err != nil {
+		t.Errorf("GetIssueLink(other tracker) = %+v, %v", missing, err)
+	}
+	list, err := db.ListIssueLinks("proj-1")
+	if err != nil || len(list) != 2 || list[0].ExternalID != "OPS-2" {
+		t.Fatalf("ListIssueLinks = %+v, %v", list, err)
+	}
+}
+
+func TestSearchDocuments_ActivitiesAndBeads(t *testing.T) {
+	db := newTestDB(t)
+	ensureProjectExists(t, db, "proj-s")
+
+	a := makeTestActivity("act-s-1")
+	a.ProjectID = "proj-s"
+	a.ActorID = "agent-s"
+	a.ResourceTitle = "Deployment failed on staging"
+	if err := db.CreateActivity(a); err != nil {
+		t.Fatalf("CreateActivity failed: %v", err)
+	}
+	doc := &search.Document{
+		Kind:       search.KindBead,
+		ID:         "bead-s-1",
+		ProjectID:  "proj-s",
+		Title:      "Fix flaky deploys",
+		Body:       "The staging deployment fails about once a day",
+		OccurredAt: time.Now(),
+	}
+	if err := db.SaveSearchDocument(doc); err != nil {
+		t.Fatalf("SaveSearchDocument failed: %v", err)
+	}
+
+	results, err := db.SearchDocuments(search.Query{Terms: []string{"deploy", "staging"}, Limit: 10})
+	if err != nil {
+		t.Fatalf("SearchDocuments failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Score <= 0 || !strings.Contains(r.Snippet, "[") {
+			t.Errorf("expected a scored, highlighted result, got %+v", r)
+		}
+	}
+
+	results, err = db.SearchDocuments(search.Query{Terms: []string{"staging"}, Kind: search.KindActivity, ActorID: "agent-s", Limit: 10})
+	if err != nil || len(results) != 1 || results[0].ID != "act-s-1" || results[0].ProjectID != "proj-s" {
+		t.Fatalf("filtered search = %+v, %v", results, err)
+	}
+	results, err = db.SearchDocuments(search.Query{Terms: []string{"staging"}, Since: time.Now().Add(time.Hour), Limit: 10})
+	if err != nil || len(results) != 0 {
+		t.Fatalf("search since the future = %+v, %v", results, err)
+	}
+
+	if err := db.DeleteSearchDocument(search.KindBead, "bead-s-1"); err != nil {
+		t.Fatalf("DeleteSearchDocument failed: %v", err)
+	}
+	results, err = db.SearchDocuments(search.Query{Terms: []string{"flaky"}, Limit: 10})
+	if err != nil || len(results) != 0 {
+		t.Fatalf("search after delete = %+v, %v", results, err)
+	}
+}
+
+func TestProposedPatches_ReviewOnce(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i, fp := range []string{"fp-1", "fp-2"} {
+		p := &models.ProposedPatch{
+			ID: fmt.Sprintf("patch-%d", i+1), ProjectID: "proj-1", BeadID: "bd-1", AgentID: "agent-1",
+			Message: "Update docs", Files: []string{"docs/guide.md"}, Diff: "+hello\n", Fingerprint: fp,
+			LowRisk: i == 0, Status: models.PatchStatusPending, CreatedAt: now.Add(time.Duration(i) * time.Minute),
+		}
+		if err := db.CreateProposedPatch(p); err != nil {
+			t.Fatalf("CreateProposedPatch failed: %v", err)
+		}
+	}
+
+	got, err := db.FindProposedPatch("proj-1", "fp-1")
+	if err != nil || got == nil || got.ID != "patch-1" || !got.LowRisk || len(got.Files) != 1 || got.ReviewedAt != nil {
+		t.Fatalf("patch not round-tripped: %+v (%v)", got, err)
+	}
+	if ok, err := db.ReviewProposedPatch("patch-1", models.PatchStatusApproved, "u-1", "", now); !ok || err != nil {
+		t.Fatalf("ReviewProposedPatch = %v, %v", ok, err)
+	}
+	if ok, _ := db.ReviewProposedPatch("patch-1", models.PatchStatusRejected, "u-2", "no", now); ok {
+		t.Error("expected a reviewed patch not to be reviewed again")
+	}
+	if got, _ := db.GetProposedPatch("patch-1"); got == nil || got.Status != models.PatchStatusApproved || got.ReviewedBy != "u-1" || got.ReviewedAt == nil {
+		t.Errorf("review not stored: %+v", got)
+	}
+	pending, err := db.ListProposedPatches("proj-1", models.PatchStatusPending)
+	if err != nil || len(pending) != 1 || pending[0].ID != "patch-2" {
+		t.Fatalf("ListProposedPatches(pending) = %+v, %v", pending, err)
+	}
+	if missing, err := db.FindProposedPatch("proj-2", "fp-1"); missing != nil || err != nil {
+		t.Errorf("FindProposedPatch(other project) = %+v, %v", missing, err)
+	}
+}
+
+func TestEvaluations_RoundTripAndQuality(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	j := &evaluation.Judge{
+		ID: "judge-1", ProjectID: "proj-1", Name: "Reviewer", Persona: "default/code-reviewer",
+		Criteria: evaluation.DefaultCriteria(), Enabled: true, CreatedAt: now, UpdatedAt: now,
+	}
+	if err := db.SaveJudge(j); err != nil {
+		t.Fatalf("SaveJudge failed: %v", err)
+	}
+	j.Enabled = false
+	j.Prompt = "Be strict"
+	if err := db.SaveJudge(j); err != nil {
+		t.Fatalf("SaveJudge(update) failed: %v", err)
+	}
+	got, err := db.GetJudge("judge-1")
+	if err != nil || got == nil || got.Enabled || got.Prompt != "Be strict" || len(got.Criteria) != 3 {
+		t.Fatalf("judge not round-tripped: %+v (%v)", got, err)
+	}
+	if missing, err := db.GetJudge("nope"); missing != nil || err != nil {
+		t.Errorf("GetJudge(unknown) = %+v, %v", missing, err)
+	}
+
+	for i, e := range []*evaluation.Evaluation{
+		{ProviderID: "p-1", Score: 0.8, CreatedAt: now.Add(-time.Hour)},
+		{ProviderID: "p-1", Score: 0.6, CreatedAt: now},
+		{ProviderID: "p-1", Error: "judge failed", CreatedAt: now},
+		{ProviderID: "p-2", Score: 0.9, CreatedAt: now.Add(-48 * time.Hour)},
+	} {
+		e.ID = fmt.Sprintf("eval-%d", i+1)
+		e.JudgeID = "judge-1"
+		e.ProjectID = "proj-1"
+		e.BeadID = "bd-1"
+		e.Scores = []evaluation.CriterionScore{{Criterion: "correctness", Score: e.Score}}
+		if err := db.SaveEvaluation(e); err != nil {
+			t.Fatalf("SaveEvaluation failed: %v", err)
+		}
+	}
+
+	list, err := db.ListEvaluations(evaluation.Filter{ProjectID: "proj-1", ProviderID: "p-1", Limit: 2})
+	if err != nil || len(list) != 2 || list[0].CreatedAt.Before(list[1].CreatedAt) || len(list[0].Scores) != 1 {
+		t.Fatalf("ListEvaluations = %+v, %v", list, err)
+	}
+
+	quality, err := db.ProviderQuality(now.Add(-24 * time.Hour))
+	if err != nil || len(quality) != 1 {
+		t.Fatalf("ProviderQuality = %+v, %v", quality, err)
+	}
+	if q := quality[0]; q.ProviderID != "p-1" || q.Evaluations != 2 || q.Quality < 0.69 || q.Quality > 0.71 {
+		t.Errorf("unexpected quality: %+v", q)
+	}
+
+	if err := db.DeleteJudge("judge-1"); err != nil {
+		t.Fatalf("DeleteJudge failed: %v", err)
+	}
+	if judges, _ := db.ListJudges("proj-1"); len(judges) != 0 {
+		t.Errorf("expected no judges after delete, got %d", len(judges))
+	}
+}
+
+func TestBenchmarkRuns_RoundTrip(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i := 0; i < 3; i++ {
+		r := &benchmark.Run{
+			ID: fmt.Sprintf("run-%d", i+1), Trigger: benchmark.TriggerManual, Tasks: 1, Passed: 1,
+			Providers: []benchmark.ProviderSummary{{ProviderID: "p-1", SuccessRate: 1, Tiers: []benchmark.TierStats{{Tier: benchmark.TierSmall, Tasks: 1, Passed: 1}}}},
+			Results:   []benchmark.Result{{TaskID: "arithmetic", Tier: benchmark.TierSmall, ProviderID: "p-1", Passed: true, Score: 1}},
+			StartedAt: now.Add(time.Duration(i) * time.Hour), FinishedAt: now.Add(time.Duration(i)*time.Hour + time.Minute),
+		}
+		if err := db.SaveBenchmarkRun(r); err != nil {
+			t.Fatalf("SaveBenchmarkRun failed: %v", err)
+		}
+	}
+
+	got, err := db.GetBenchmarkRun("run-1")
+	if err != nil || got == nil || len(got.Providers) != 1 || len(got.Providers[0].Tiers) != 1 || len(got.Results) != 1 || !got.Results[0].Passed {
+		t.Fatalf("run not round-tripped: %+v (%v)", got, err)
+	}
+	if missing, err := db.GetBenchmarkRun("nope"); missing != nil || err != nil {
+		t.Errorf("GetBenchmarkRun(unknown) = %+v, %v", missing, err)
+	}
+	list, err := db.ListBenchmarkRuns(2)
+	if err != nil || len(list) != 2 || list[0].ID != "run-3" {
+		t.Fatalf("ListBenchmarkRuns = %+v, %v", list, err)
+	}
+}
+
+func TestProviderMetrics_SaveAndHistory(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	for i := 0; i < 3; i++ {
+		at := now.Add(time.Duration(i-2) * time.Hour)
+		err := db.SaveProviderMetrics([]provider.ScoreMetrics{
+			{ProviderID: "p-1", ModelParamsB: 70, AvgRequestLatencyMs: float64(100 * (i + 1)), Requests: 4, Failures: 1, CompositeScore: 1000, RecordedAt: at},
+			{ProviderID: "p-2", ModelParamsB: 8, Requests: 2, RecordedAt: at},
+		})
+		if err != nil {
+			t.Fatalf("SaveProviderMetrics failed: %v", err)
+		}
+	}
+
+	current, err := db.ListProviderMetrics()
+	if err != nil || len(current) != 2 {
+		t.Fatalf("ListProviderMetrics = %+v, %v", current, err)
+	}
+	if current[0].ProviderID != "p-1" || current[0].AvgRequestLatencyMs != 300 || current[0].ErrorRate != 0.25 || !current[0].RecordedAt.Equal(now) {
+		t.Errorf("expected the latest metrics of p-1, got %+v", current[0])
+	}
+
+	history, err := db.ListProviderMetricsHistory("p-1", now.Add(-90*time.Minute), 0)
+	if err != nil || len(history) != 2 || history[0].AvgRequestLatencyMs != 200 || history[1].AvgRequestLatencyMs != 300 {
+		t.Fatalf("ListProviderMetricsHistory = %+v, %v", history, err)
+	}
+	if all, _ := db.ListProviderMetricsHistory("", now.Add(-24*time.Hour), 4); len(all) != 4 {
+		t.Errorf("expected the newest 4 snapshots of all providers, got %d", len(all))
+	}
+
+	if n, err := db.DeleteProviderMetricsHistory(now.Add(-90 * time.Minute)); err != nil || n != 2 {
+		t.Errorf("DeleteProviderMetricsHistory = %d, %v", n, err)
+	}
+	if err := db.DeleteProviderMetrics("p-2"); err != nil {
+		t.Fatalf("DeleteProviderMetrics failed: %v", err)
+	}
+	if current, _ := db.ListProviderMetrics(); len(current) != 1 {
+		t.Errorf("expected p-2's metrics to be gone, got %+v", current)
+	}
+}
+
+func TestEmbeddingCache_StoreAndPrune(t *testing.T) {
+	db := newTestDB(t)
+
+	err := db.StoreCachedEmbeddings("provider:nomic", map[string][]float32{
+		"k1": {0.5, -1},
+		"k2": {1, 0},
+	})
+	if err != nil {
+		t.Fatalf("StoreCachedEmbeddings failed: %v", err)
+	}
+	if err := db.StoreCachedEmbeddings("provider:nomic", map[string][]float32{"k2": {0, 1}}); err != nil {
+		t.Fatalf("StoreCachedEmbeddings overwrite failed: %v", err)
+	}
+
+	found, err := db.GetCachedEmbeddings([]string{"k1", "k2", "k3"})
+	if err != nil || len(found) != 2 {
+		t.Fatalf("GetCachedEmbeddings = %v, %v", found, err)
+	}
+	if found["k1"][0] != 0.5 || found["k1"][1] != -1 || found["k2"][1] != 1 {
+		t.Errorf("unexpected embeddings %v", found)
+	}
+
+	if n, err := db.DeleteCachedEmbeddings(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Errorf("expected fresh embeddings to be kept, got %d, %v", n, err)
+	}
+	if n, err := db.DeleteCachedEmbeddings(time.Now().Add(time.Minute)); err != nil || n != 2 {
+		t.Errorf("DeleteCachedEmbeddings = %d, %v", n, err)
+	}
+}
+
+func TestSearchLessonsBySimilarity_Pages(t *testing.T) {
+	db := newTestDB(t)
+	db.SetLessonSearchConfig(LessonSearchConfig{PageSize: 3})
+	base := time.Now().Add(-time.Hour)
+
+	// The best match is the oldest lesson, several pages in; two lessons
+	// share each timestamp so pages split between equal ones
+	for i := 0; i < 10; i++ {
+		embedding := []float32{0, 1}
+		if i == 0 {
+			embedding = []float32{1, 0}
+		}
+		lesson := &models.Lesson{
+			ID: fmt.Sprintf("page-%02d", i), ProjectID: "proj-pages", Category: "test", Title: "t", Detail: "d",
+			CreatedAt: base.Add(time.Duration(i/2) * time.Minute),
+		}
+		if err := db.StoreLessonWithEmbedding(lesson, embedding); err != nil {
+			t.Fatalf("StoreLessonWithEmbedding failed: %v", err)
+		}
+	}
+
+	results, err := db.SearchLessonsBySimilarity("proj-pages", []float32{1, 0}, 20)
+	if err != nil || len(results) != 10 {
+		t.Fatalf("expected every lesson exactly once, got %d, %v", len(results), err)
+	}
+	if results[0].ID != "page-00" || results[0].RelevanceScore >= 1 {
+		t.Errorf("expected the oldest lesson first with a decayed relevance, got %s (%v)", results[0].ID, results[0].RelevanceScore)
+	}
+
+	db.SetLessonSearchConfig(LessonSearchConfig{PageSize: 3, MaxCandidates: 4})
+	if results, _ := db.SearchLessonsBySimilarity("proj-pages", []float32{1, 0}, 20); len(results) != 4 || results[0].ID == "page-00" {
+		t.Errorf("expected only the 4 newest lessons compared, got %d", len(results))
+	}
+
+	ranked, err := db.RankLessons([]string{"page-05", "page-00", "missing"}, map[string][]float32{"": {1, 0}}, 5)
+	if err != nil || len(ranked) != 2 || ranked[0].ID != "page-00" {
+		t.Errorf("RankLessons = %v, %v", ranked, err)
+	}
+
+	versions, err := db.ListLessonEmbeddingVersions("proj-pages")
+	if err != nil || len(versions) != 10 {
+		t.Fatalf("ListLessonEmbeddingVersions = %v, %v", versions, err)
+	}
+	embeddings, err := db.GetLessonEmbeddings([]string{"page-00", "missing"})
+	if err != nil || len(embeddings) != 1 || embeddings["page-00"][0] != 1 {
+		t.Errorf("GetLessonEmbeddings = %v, %v", embeddings, err)
+	}
+}
@@ -0,0 +1,109 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/costestimate"
+)
+
+const dispatchEstimateColumns = `id, bead_id, project_id, task_id, provider_id, model, complexity,
+	prompt_tokens, iterations, tokens, cost_usd, action, reason,
+	actual_tokens, actual_iterations, actual_cost_usd, created_at, completed_at`
+
+// SaveDispatchEstimate inserts or replaces a dispatch cost estimate
+func (d *Database) SaveDispatchEstimate(e *costestimate.Estimate) error {
+	query := `
+		INSERT INTO dispatch_estimates (` + dispatchEstimateColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			action = excluded.action,
+			reason = excluded.reason,
+			actual_tokens = excluded.actual_tokens,
+			actual_iterations = excluded.actual_iterations,
+			actual_cost_usd = excluded.actual_cost_usd,
+			completed_at = excluded.completed_at
+	`
+	_, err := d.exec(query,
+		e.ID, e.BeadID, e.ProjectID, e.TaskID, e.ProviderID, e.Model, e.Complexity,
+		e.PromptTokens, e.Iterations, e.Tokens, e.CostUSD, e.Action, e.Reason,
+		e.ActualTokens, e.ActualIterations, e.ActualCostUSD, e.CreatedAt, sqlNullTime(e.CompletedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save dispatch estimate: %w", err)
+	}
+	return nil
+}
+
+// GetDispatchEstimateByTask returns the estimate made for a dispatch, or
+// nil if it has none
+func (d *Database) GetDispatchEstimateByTask(taskID string) (*costestimate.Estimate, error) {
+	row := d.queryRow(`SELECT `+dispatchEstimateColumns+` FROM dispatch_estimates WHERE task_id = ?`, taskID)
+	e, err := scanDispatchEstimate(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispatch estimate: %w", err)
+	}
+	return e, nil
+}
+
+// ListDispatchEstimates returns dispatch estimates, newest first
+func (d *Database) ListDispatchEstimates(f costestimate.Filter) ([]*costestimate.Estimate, error) {
+	var where []string
+	var args []interface{}
+	if f.BeadID != "" {
+		where = append(where, "bead_id = ?")
+		args = append(args, f.BeadID)
+	}
+	if f.ProjectID != "" {
+		where = append(where, "project_id = ?")
+		args = append(args, f.ProjectID)
+	}
+	if f.Completed {
+		where = append(where, "completed_at IS NOT NULL")
+	}
+	query := `SELECT ` + dispatchEstimateColumns + ` FROM dispatch_estimates`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at DESC`
+	if f.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, f.Limit)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispatch estimates: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*costestimate.Estimate
+	for rows.Next() {
+		e, err := scanDispatchEstimate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispatch estimate: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+func scanDispatchEstimate(row interface{ Scan(...interface{}) error }) (*costestimate.Estimate, error) {
+	e := &costestimate.Estimate{}
+	var completedAt sql.NullTime
+	err := row.Scan(&e.ID, &e.BeadID, &e.ProjectID, &e.TaskID, &e.ProviderID, &e.Model, &e.Complexity,
+		&e.PromptTokens, &e.Iterations, &e.Tokens, &e.CostUSD, &e.Action, &e.Reason,
+		&e.ActualTokens, &e.ActualIterations, &e.ActualCostUSD, &e.CreatedAt, &completedAt)
+	if err != nil {
+		return nil, err
+	}
+	if completedAt.Valid {
+		t := completedAt.Time
+		e.CompletedAt = &t
+	}
+	return e, nil
+}
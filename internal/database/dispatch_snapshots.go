@@ -0,0 +1,185 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Dispatch snapshot statuses
+const (
+	DispatchSnapshotRunning   = "running"
+	DispatchSnapshotCompleted = "completed"
+	DispatchSnapshotReverted  = "reverted"
+)
+
+// DispatchSnapshot records a bead's state before a dispatch and the git
+// changes the dispatch made, so the dispatch can be rolled back
+type DispatchSnapshot struct {
+	ID              string     `json:"id"` // The dispatched task's ID
+	BeadID          string     `json:"bead_id"`
+	ProjectID       string     `json:"project_id"`
+	AgentID         string     `json:"agent_id,omitempty"`
+	DispatchNumber  int        `json:"dispatch_number"`
+	BeadStatus      string     `json:"bead_status"`
+	BeadAssignedTo  string     `json:"bead_assigned_to,omitempty"`
+	BeadContextJSON string     `json:"-"`
+	WorktreeJSON    string     `json:"-"` // git.WorktreeSnapshot taken before the dispatch, if the project has a working copy
+	CommitSHAs      []string   `json:"commit_shas"`
+	Branches        []string   `json:"branches"` // Branches the dispatch created
+	PRNumber        int        `json:"pr_number,omitempty"`
+	PRURL           string     `json:"pr_url,omitempty"`
+	Status          string     `json:"status"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+	RevertedAt      *time.Time `json:"reverted_at,omitempty"`
+	RevertedBy      string     `json:"reverted_by,omitempty"`
+}
+
+const dispatchSnapshotColumns = `id, bead_id, project_id, agent_id, dispatch_number, bead_status,
+	bead_assigned_to, bead_context_json, worktree_json, commits_json, branches_json,
+	pr_number, pr_url, status, created_at, completed_at, reverted_at, reverted_by`
+
+// SaveDispatchSnapshot inserts or updates a dispatch snapshot
+func (d *Database) SaveDispatchSnapshot(s *DispatchSnapshot) error {
+	if s.CreatedAt.IsZero() {
+		s.CreatedAt = time.Now()
+	}
+	if s.BeadContextJSON == "" {
+		s.BeadContextJSON = "{}"
+	}
+	commits, err := json.Marshal(nonNilStrings(s.CommitSHAs))
+	if err != nil {
+		return fmt.Errorf("failed to encode dispatch commits: %w", err)
+	}
+	branches, err := json.Marshal(nonNilStrings(s.Branches))
+	if err != nil {
+		return fmt.Errorf("failed to encode dispatch branches: %w", err)
+	}
+
+	query := `
+		INSERT INTO dispatch_snapshots (` + dispatchSnapshotColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			commits_json = excluded.commits_json,
+			branches_json = excluded.branches_json,
+			pr_number = excluded.pr_number,
+			pr_url = excluded.pr_url,
+			status = excluded.status,
+			completed_at = excluded.completed_at,
+			reverted_at = excluded.reverted_at,
+			reverted_by = excluded.reverted_by
+	`
+
+	_, err = d.db.Exec(query,
+		s.ID,
+		s.BeadID,
+		s.ProjectID,
+		sqlNullString(s.AgentID),
+		s.DispatchNumber,
+		s.BeadStatus,
+		sqlNullString(s.BeadAssignedTo),
+		s.BeadContextJSON,
+		sqlNullString(s.WorktreeJSON),
+		string(commits),
+		string(branches),
+		s.PRNumber,
+		sqlNullString(s.PRURL),
+		s.Status,
+		s.CreatedAt,
+		sqlNullTime(s.CompletedAt),
+		sqlNullTime(s.RevertedAt),
+		sqlNullString(s.RevertedBy),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save dispatch snapshot: %w", err)
+	}
+	return nil
+}
+
+// GetDispatchSnapshot retrieves a dispatch snapshot by ID. It returns
+// nil, nil when there is no such snapshot.
+func (d *Database) GetDispatchSnapshot(id string) (*DispatchSnapshot, error) {
+	row := d.db.QueryRow(`SELECT `+dispatchSnapshotColumns+` FROM dispatch_snapshots WHERE id = ?`, id)
+	s, err := scanDispatchSnapshot(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dispatch snapshot: %w", err)
+	}
+	return s, nil
+}
+
+// ListDispatchSnapshots returns a bead's dispatch snapshots, oldest first
+func (d *Database) ListDispatchSnapshots(beadID string) ([]*DispatchSnapshot, error) {
+	rows, err := d.db.Query(`SELECT `+dispatchSnapshotColumns+` FROM dispatch_snapshots
+		WHERE bead_id = ? ORDER BY created_at ASC, dispatch_number ASC`, beadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dispatch snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []*DispatchSnapshot
+	for rows.Next() {
+		s, err := scanDispatchSnapshot(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dispatch snapshot: %w", err)
+		}
+		snapshots = append(snapshots, s)
+	}
+	return snapshots, rows.Err()
+}
+
+func scanDispatchSnapshot(row interface{ Scan(...interface{}) error }) (*DispatchSnapshot, error) {
+	s := &DispatchSnapshot{}
+	var agentID, assignedTo, worktree, prURL, revertedBy sql.NullString
+	var commits, branches string
+	var completedAt, revertedAt sql.NullTime
+
+	if err := row.Scan(
+		&s.ID,
+		&s.BeadID,
+		&s.ProjectID,
+		&agentID,
+		&s.DispatchNumber,
+		&s.BeadStatus,
+		&assignedTo,
+		&s.BeadContextJSON,
+		&worktree,
+		&commits,
+		&branches,
+		&s.PRNumber,
+		&prURL,
+		&s.Status,
+		&s.CreatedAt,
+		&completedAt,
+		&revertedAt,
+		&revertedBy,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(commits), &s.CommitSHAs); err != nil {
+		return nil, fmt.Errorf("failed to decode dispatch commits: %w", err)
+	}
+	if err := json.Unmarshal([]byte(branches), &s.Branches); err != nil {
+		return nil, fmt.Errorf("failed to decode dispatch branches: %w", err)
+	}
+	s.AgentID = agentID.String
+	s.BeadAssignedTo = assignedTo.String
+	s.WorktreeJSON = worktree.String
+	s.PRURL = prURL.String
+	s.CompletedAt = nullTimePtr(completedAt)
+	s.RevertedAt = nullTimePtr(revertedAt)
+	s.RevertedBy = revertedBy.String
+	return s, nil
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/memory"
+)
+
+// GetCachedEmbeddings returns the cached embeddings of the given keys, by
+// key; keys with no entry are left out
+func (d *Database) GetCachedEmbeddings(keys []string) (map[string][]float32, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", ")
+	args := make([]interface{}, len(keys))
+	for i, k := range keys {
+		args[i] = k
+	}
+	rows, err := d.query(`SELECT cache_key, embedding FROM embedding_cache WHERE cache_key IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	found := make(map[string][]float32, len(keys))
+	for rows.Next() {
+		var key string
+		var embBytes []byte
+		if err := rows.Scan(&key, &embBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan cached embedding: %w", err)
+		}
+		if vec := memory.DecodeEmbedding(embBytes); len(vec) > 0 {
+			found[key] = vec
+		}
+	}
+	return found, rows.Err()
+}
+
+// StoreCachedEmbeddings caches embeddings of the given version by key, in
+// one transaction
+func (d *Database) StoreCachedEmbeddings(version string, entries map[string][]float32) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsert := d.rebind(`
+		INSERT INTO embedding_cache (cache_key, version, embedding, created_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(cache_key) DO UPDATE SET
+			embedding = excluded.embedding,
+			created_at = excluded.created_at
+	`)
+	now := time.Now().UTC()
+	for key, vec := range entries {
+		if _, err := tx.Exec(upsert, key, version, memory.EncodeEmbedding(vec), now); err != nil {
+			return fmt.Errorf("failed to cache embedding: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteCachedEmbeddings removes the embeddings cached before before and
+// returns how many there were
+func (d *Database) DeleteCachedEmbeddings(before time.Time) (int64, error) {
+	res, err := d.exec(`DELETE FROM embedding_cache WHERE created_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cached embeddings: %w", err)
+	}
+	return res.RowsAffected()
+}
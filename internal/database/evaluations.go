@@ -0,0 +1,178 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/evaluation"
+)
+
+const judgeColumns = `id, project_id, name, persona, provider_id, prompt, criteria_json, enabled, created_by, created_at, updated_at`
+
+const evaluationColumns = `id, judge_id, judge_name, project_id, bead_id, dispatch_id, agent_id, provider_id, model,
+	judge_provider_id, judge_model, scores_json, score, summary, response, error, created_at`
+
+// SaveJudge inserts or updates a judge
+func (d *Database) SaveJudge(j *evaluation.Judge) error {
+	criteria, err := json.Marshal(j.Criteria)
+	if err != nil {
+		return fmt.Errorf("failed to encode judge criteria: %w", err)
+	}
+	enabled := 0
+	if j.Enabled {
+		enabled = 1
+	}
+	_, err = d.exec(`
+		INSERT INTO judges (`+judgeColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			persona = excluded.persona,
+			provider_id = excluded.provider_id,
+			prompt = excluded.prompt,
+			criteria_json = excluded.criteria_json,
+			enabled = excluded.enabled,
+			updated_at = excluded.updated_at
+	`, j.ID, j.ProjectID, j.Name, j.Persona, j.ProviderID, j.Prompt, string(criteria), enabled, j.CreatedBy, j.CreatedAt, j.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save judge: %w", err)
+	}
+	return nil
+}
+
+// GetJudge returns a judge, or nil if it does not exist
+func (d *Database) GetJudge(id string) (*evaluation.Judge, error) {
+	j, err := scanJudge(d.queryRow(`SELECT `+judgeColumns+` FROM judges WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return j, err
+}
+
+// ListJudges returns a project's judges, oldest first
+func (d *Database) ListJudges(projectID string) ([]*evaluation.Judge, error) {
+	rows, err := d.query(`SELECT `+judgeColumns+` FROM judges WHERE project_id = ? ORDER BY created_at ASC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list judges: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*evaluation.Judge
+	for rows.Next() {
+		j, err := scanJudge(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, j)
+	}
+	return list, rows.Err()
+}
+
+// DeleteJudge removes a judge
+func (d *Database) DeleteJudge(id string) error {
+	if _, err := d.exec(`DELETE FROM judges WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete judge: %w", err)
+	}
+	return nil
+}
+
+func scanJudge(row interface{ Scan(...interface{}) error }) (*evaluation.Judge, error) {
+	j := &evaluation.Judge{}
+	var criteria string
+	var enabled int
+	if err := row.Scan(&j.ID, &j.ProjectID, &j.Name, &j.Persona, &j.ProviderID, &j.Prompt, &criteria, &enabled,
+		&j.CreatedBy, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan judge: %w", err)
+	}
+	if err := json.Unmarshal([]byte(criteria), &j.Criteria); err != nil {
+		return nil, fmt.Errorf("failed to decode judge criteria: %w", err)
+	}
+	j.Enabled = enabled != 0
+	return j, nil
+}
+
+// SaveEvaluation stores a judge's grading of a dispatch
+func (d *Database) SaveEvaluation(e *evaluation.Evaluation) error {
+	scores, err := json.Marshal(e.Scores)
+	if err != nil {
+		return fmt.Errorf("failed to encode evaluation scores: %w", err)
+	}
+	_, err = d.exec(`INSERT INTO evaluations (`+evaluationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ID, e.JudgeID, e.JudgeName, e.ProjectID, e.BeadID, e.DispatchID, e.AgentID, e.ProviderID, e.Model,
+		e.JudgeProviderID, e.JudgeModel, string(scores), e.Score, e.Summary, e.Response, e.Error, e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save evaluation: %w", err)
+	}
+	return nil
+}
+
+// ListEvaluations returns the evaluations matching filter, newest first
+func (d *Database) ListEvaluations(filter evaluation.Filter) ([]*evaluation.Evaluation, error) {
+	query := `SELECT ` + evaluationColumns + ` FROM evaluations WHERE 1 = 1`
+	var args []interface{}
+	if filter.ProjectID != "" {
+		query += ` AND project_id = ?`
+		args = append(args, filter.ProjectID)
+	}
+	if filter.BeadID != "" {
+		query += ` AND bead_id = ?`
+		args = append(args, filter.BeadID)
+	}
+	if filter.ProviderID != "" {
+		query += ` AND provider_id = ?`
+		args = append(args, filter.ProviderID)
+	}
+	query += ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list evaluations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*evaluation.Evaluation
+	for rows.Next() {
+		e := &evaluation.Evaluation{}
+		var scores string
+		if err := rows.Scan(&e.ID, &e.JudgeID, &e.JudgeName, &e.ProjectID, &e.BeadID, &e.DispatchID, &e.AgentID,
+			&e.ProviderID, &e.Model, &e.JudgeProviderID, &e.JudgeModel, &scores, &e.Score, &e.Summary, &e.Response,
+			&e.Error, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan evaluation: %w", err)
+		}
+		if err := json.Unmarshal([]byte(scores), &e.Scores); err != nil {
+			return nil, fmt.Errorf("failed to decode evaluation scores: %w", err)
+		}
+		list = append(list, e)
+	}
+	return list, rows.Err()
+}
+
+// ProviderQuality returns the mean score of each provider's evaluations
+// since a time, leaving out evaluations whose judge failed
+func (d *Database) ProviderQuality(since time.Time) ([]evaluation.ProviderQuality, error) {
+	rows, err := d.query(`SELECT provider_id, AVG(score), COUNT(*) FROM evaluations
+		WHERE error = '' AND created_at >= ? GROUP BY provider_id ORDER BY provider_id`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute provider quality: %w", err)
+	}
+	defer rows.Close()
+
+	var list []evaluation.ProviderQuality
+	for rows.Next() {
+		var q evaluation.ProviderQuality
+		if err := rows.Scan(&q.ProviderID, &q.Quality, &q.Evaluations); err != nil {
+			return nil, fmt.Errorf("failed to scan provider quality: %w", err)
+		}
+		list = append(list, q)
+	}
+	return list, rows.Err()
+}
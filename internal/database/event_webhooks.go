@@ -0,0 +1,246 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+)
+
+const eventWebhookColumns = `id, name, url, secret, event_types_json, project_ids_json, enabled, created_by, created_at, updated_at`
+
+const webhookDeliveryColumns = `id, subscription_id, event_id, event_type, project_id, payload, status, attempts,
+	response_code, error, next_attempt_at, created_at, updated_at`
+
+// SaveEventWebhook inserts or updates an event webhook subscription
+func (d *Database) SaveEventWebhook(s *eventhooks.Subscription) error {
+	eventTypes, err := json.Marshal(s.EventTypes)
+	if err != nil {
+		return fmt.Errorf("failed to encode event types: %w", err)
+	}
+	var projectIDs sql.NullString
+	if len(s.ProjectIDs) > 0 {
+		data, err := json.Marshal(s.ProjectIDs)
+		if err != nil {
+			return fmt.Errorf("failed to encode project IDs: %w", err)
+		}
+		projectIDs = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := `
+		INSERT INTO event_webhooks (` + eventWebhookColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			url = excluded.url,
+			secret = excluded.secret,
+			event_types_json = excluded.event_types_json,
+			project_ids_json = excluded.project_ids_json,
+			enabled = excluded.enabled,
+			updated_at = excluded.updated_at
+	`
+	_, err = d.db.Exec(query,
+		s.ID,
+		s.Name,
+		s.URL,
+		s.Secret,
+		string(eventTypes),
+		projectIDs,
+		s.Enabled,
+		sqlNullString(s.CreatedBy),
+		s.CreatedAt,
+		s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save event webhook: %w", err)
+	}
+	return nil
+}
+
+// GetEventWebhook returns a subscription, or nil if it does not exist
+func (d *Database) GetEventWebhook(id string) (*eventhooks.Subscription, error) {
+	row := d.db.QueryRow(`SELECT `+eventWebhookColumns+` FROM event_webhooks WHERE id = ?`, id)
+	s, err := scanEventWebhook(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// ListEventWebhooks returns all subscriptions, oldest first
+func (d *Database) ListEventWebhooks() ([]*eventhooks.Subscription, error) {
+	rows, err := d.db.Query(`SELECT ` + eventWebhookColumns + ` FROM event_webhooks ORDER BY created_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list event webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*eventhooks.Subscription
+	for rows.Next() {
+		s, err := scanEventWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// DeleteEventWebhook removes a subscription and its delivery log
+func (d *Database) DeleteEventWebhook(id string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE subscription_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete webhook deliveries: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM event_webhooks WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete event webhook: %w", err)
+	}
+	return tx.Commit()
+}
+
+func scanEventWebhook(row interface{ Scan(...interface{}) error }) (*eventhooks.Subscription, error) {
+	s := &eventhooks.Subscription{}
+	var eventTypes string
+	var projectIDs, createdBy sql.NullString
+	if err := row.Scan(&s.ID, &s.Name, &s.URL, &s.Secret, &eventTypes, &projectIDs,
+		&s.Enabled, &createdBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan event webhook: %w", err)
+	}
+	if err := json.Unmarshal([]byte(eventTypes), &s.EventTypes); err != nil {
+		return nil, fmt.Errorf("failed to decode event types: %w", err)
+	}
+	if projectIDs.Valid && projectIDs.String != "" {
+		if err := json.Unmarshal([]byte(projectIDs.String), &s.ProjectIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode project IDs: %w", err)
+		}
+	}
+	s.CreatedBy = createdBy.String
+	return s, nil
+}
+
+// SaveWebhookDelivery inserts or updates a delivery log entry
+func (d *Database) SaveWebhookDelivery(w *eventhooks.Delivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (` + webhookDeliveryColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			attempts = excluded.attempts,
+			response_code = excluded.response_code,
+			error = excluded.error,
+			next_attempt_at = excluded.next_attempt_at,
+			updated_at = excluded.updated_at
+	`
+	_, err := d.db.Exec(query,
+		w.ID,
+		w.SubscriptionID,
+		sqlNullString(w.EventID),
+		w.EventType,
+		sqlNullString(w.ProjectID),
+		w.Payload,
+		w.Status,
+		w.Attempts,
+		w.ResponseCode,
+		sqlNullString(w.Error),
+		sqlNullTime(w.NextAttemptAt),
+		w.CreatedAt,
+		w.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// GetWebhookDelivery returns a delivery, or nil if it does not exist
+func (d *Database) GetWebhookDelivery(id string) (*eventhooks.Delivery, error) {
+	row := d.db.QueryRow(`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id)
+	w, err := scanWebhookDelivery(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return w, err
+}
+
+// ListWebhookDeliveries returns a page of the delivery log and the number
+// of entries matching f
+func (d *Database) ListWebhookDeliveries(f eventhooks.DeliveryFilter) ([]*eventhooks.Delivery, int, error) {
+	var where []string
+	var args []interface{}
+	if f.SubscriptionID != "" {
+		where = append(where, "subscription_id = ?")
+		args = append(args, f.SubscriptionID)
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, f.Status)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM webhook_deliveries`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook deliveries: %w", err)
+	}
+
+	cols := []string{"created_at", "id"}
+	if f.After != nil {
+		after, afterArgs, err := keysetAfter(cols, f.After, !f.Ascending)
+		if err != nil {
+			return nil, 0, err
+		}
+		where = append(where, after)
+		args = append(args, afterArgs...)
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries` + clause + orderBy(cols, !f.Ascending)
+	if f.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*eventhooks.Delivery
+	for rows.Next() {
+		w, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, w)
+	}
+	return list, total, rows.Err()
+}
+
+func scanWebhookDelivery(row interface{ Scan(...interface{}) error }) (*eventhooks.Delivery, error) {
+	w := &eventhooks.Delivery{}
+	var eventID, projectID, errMsg sql.NullString
+	var nextAttempt sql.NullTime
+	if err := row.Scan(&w.ID, &w.SubscriptionID, &eventID, &w.EventType, &projectID, &w.Payload,
+		&w.Status, &w.Attempts, &w.ResponseCode, &errMsg, &nextAttempt, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+	}
+	w.EventID = eventID.String
+	w.ProjectID = projectID.String
+	w.Error = errMsg.String
+	w.NextAttemptAt = nullTimePtr(nextAttempt)
+	return w, nil
+}
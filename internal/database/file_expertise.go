@@ -0,0 +1,104 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// FileExpertise records an agent's successful modifications to one file
+type FileExpertise struct {
+	ProjectID      string    `json:"project_id"`
+	AgentID        string    `json:"agent_id"`
+	PersonaName    string    `json:"persona_name,omitempty"`
+	FilePath       string    `json:"file_path"`
+	EditCount      int       `json:"edit_count"`
+	LastBeadID     string    `json:"last_bead_id,omitempty"`
+	LastModifiedAt time.Time `json:"last_modified_at"`
+}
+
+const fileExpertiseColumns = `project_id, agent_id, persona_name, file_path, edit_count, last_bead_id, last_modified_at`
+
+// RecordFileExpertise credits an agent with one successful edit to each path
+func (d *Database) RecordFileExpertise(projectID, agentID, personaName, beadID string, paths []string) error {
+	if projectID == "" || agentID == "" || len(paths) == 0 {
+		return nil
+	}
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	query := `
+		INSERT INTO agent_file_expertise (` + fileExpertiseColumns + `)
+		VALUES (?, ?, ?, ?, 1, ?, ?)
+		ON CONFLICT(project_id, agent_id, file_path) DO UPDATE SET
+			persona_name = excluded.persona_name,
+			edit_count = agent_file_expertise.edit_count + 1,
+			last_bead_id = excluded.last_bead_id,
+			last_modified_at = excluded.last_modified_at
+	`
+	now := time.Now().UTC()
+	for _, path := range paths {
+		if _, err := tx.Exec(query, projectID, agentID, sqlNullString(personaName), path, sqlNullString(beadID), now); err != nil {
+			return fmt.Errorf("failed to record file expertise: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// GetAgentFileExpertise returns the files an agent has modified in a
+// project, most recently touched first
+func (d *Database) GetAgentFileExpertise(projectID, agentID string, limit int) ([]*FileExpertise, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `SELECT ` + fileExpertiseColumns + `
+		FROM agent_file_expertise
+		WHERE project_id = ? AND agent_id = ?
+		ORDER BY last_modified_at DESC, edit_count DESC
+		LIMIT ?`
+	return d.queryFileExpertise(query, projectID, agentID, limit)
+}
+
+// GetFileExperts returns every agent's expertise on the given files
+func (d *Database) GetFileExperts(projectID string, paths []string) ([]*FileExpertise, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(paths)), ", ")
+	args := make([]interface{}, 0, len(paths)+1)
+	args = append(args, projectID)
+	for _, p := range paths {
+		args = append(args, p)
+	}
+	query := `SELECT ` + fileExpertiseColumns + `
+		FROM agent_file_expertise
+		WHERE project_id = ? AND file_path IN (` + placeholders + `)
+		ORDER BY edit_count DESC`
+	return d.queryFileExpertise(query, args...)
+}
+
+func (d *Database) queryFileExpertise(query string, args ...interface{}) ([]*FileExpertise, error) {
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query file expertise: %w", err)
+	}
+	defer rows.Close()
+
+	var out []*FileExpertise
+	for rows.Next() {
+		fe := &FileExpertise{}
+		var persona, beadID sql.NullString
+		if err := rows.Scan(&fe.ProjectID, &fe.AgentID, &persona, &fe.FilePath, &fe.EditCount, &beadID, &fe.LastModifiedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan file expertise: %w", err)
+		}
+		fe.PersonaName = persona.String
+		fe.LastBeadID = beadID.String
+		out = append(out, fe)
+	}
+	return out, rows.Err()
+}
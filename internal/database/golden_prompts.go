@@ -0,0 +1,188 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+)
+
+const goldenCaseColumns = `id, project_id, name, system_prompt, prompt, expectations_json, provider_ids_json, enabled, created_by, created_at, updated_at`
+
+const goldenRunColumns = `id, project_id, trigger, reason, status, cases, passed, failed, regressions, results_json, started_at, finished_at`
+
+// SaveGoldenCase inserts or replaces a golden prompt case
+func (d *Database) SaveGoldenCase(c *goldenprompts.Case) error {
+	expectations, err := json.Marshal(c.Expectations)
+	if err != nil {
+		return fmt.Errorf("failed to encode expectations: %w", err)
+	}
+	providers, err := json.Marshal(c.ProviderIDs)
+	if err != nil {
+		return fmt.Errorf("failed to encode provider ids: %w", err)
+	}
+	_, err = d.db.Exec(`INSERT OR REPLACE INTO golden_prompt_cases (`+goldenCaseColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.ID,
+		c.ProjectID,
+		c.Name,
+		sqlNullString(c.SystemPrompt),
+		c.Prompt,
+		string(expectations),
+		string(providers),
+		c.Enabled,
+		sqlNullString(c.CreatedBy),
+		c.CreatedAt,
+		c.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save golden prompt: %w", err)
+	}
+	return nil
+}
+
+// GetGoldenCase returns a golden prompt case, or nil if it does not exist
+func (d *Database) GetGoldenCase(id string) (*goldenprompts.Case, error) {
+	c, err := scanGoldenCase(d.db.QueryRow(`SELECT `+goldenCaseColumns+` FROM golden_prompt_cases WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return c, err
+}
+
+// ListGoldenCases returns a project's golden prompt cases, or every case
+// when projectID is empty, oldest first
+func (d *Database) ListGoldenCases(projectID string) ([]*goldenprompts.Case, error) {
+	query := `SELECT ` + goldenCaseColumns + ` FROM golden_prompt_cases`
+	var args []interface{}
+	if projectID != "" {
+		query += ` WHERE project_id = ?`
+		args = append(args, projectID)
+	}
+	rows, err := d.db.Query(query+` ORDER BY created_at`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list golden prompts: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*goldenprompts.Case
+	for rows.Next() {
+		c, err := scanGoldenCase(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, c)
+	}
+	return list, rows.Err()
+}
+
+// DeleteGoldenCase removes a golden prompt case
+func (d *Database) DeleteGoldenCase(id string) error {
+	if _, err := d.db.Exec(`DELETE FROM golden_prompt_cases WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete golden prompt: %w", err)
+	}
+	return nil
+}
+
+// SaveGoldenRun inserts or replaces a golden prompt run
+func (d *Database) SaveGoldenRun(r *goldenprompts.Run) error {
+	results, err := json.Marshal(r.Results)
+	if err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+	_, err = d.db.Exec(`INSERT OR REPLACE INTO golden_prompt_runs (`+goldenRunColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID,
+		r.ProjectID,
+		r.Trigger,
+		sqlNullString(r.Reason),
+		r.Status,
+		r.Cases,
+		r.Passed,
+		r.Failed,
+		r.Regressions,
+		string(results),
+		r.StartedAt,
+		r.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save golden prompt run: %w", err)
+	}
+	return nil
+}
+
+// GetGoldenRun returns a golden prompt run, or nil if it does not exist
+func (d *Database) GetGoldenRun(id string) (*goldenprompts.Run, error) {
+	r, err := scanGoldenRun(d.db.QueryRow(`SELECT `+goldenRunColumns+` FROM golden_prompt_runs WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListGoldenRuns returns a project's golden prompt runs, newest first;
+// limit 0 means no limit
+func (d *Database) ListGoldenRuns(projectID string, limit int) ([]*goldenprompts.Run, error) {
+	query := `SELECT ` + goldenRunColumns + ` FROM golden_prompt_runs WHERE project_id = ? ORDER BY started_at DESC`
+	args := []interface{}{projectID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list golden prompt runs: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*goldenprompts.Run
+	for rows.Next() {
+		r, err := scanGoldenRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func scanGoldenCase(row interface{ Scan(...interface{}) error }) (*goldenprompts.Case, error) {
+	c := &goldenprompts.Case{}
+	var expectations string
+	var systemPrompt, providers, createdBy sql.NullString
+	if err := row.Scan(&c.ID, &c.ProjectID, &c.Name, &systemPrompt, &c.Prompt, &expectations, &providers,
+		&c.Enabled, &createdBy, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan golden prompt: %w", err)
+	}
+	if err := json.Unmarshal([]byte(expectations), &c.Expectations); err != nil {
+		return nil, fmt.Errorf("failed to decode expectations: %w", err)
+	}
+	if providers.Valid && providers.String != "" {
+		if err := json.Unmarshal([]byte(providers.String), &c.ProviderIDs); err != nil {
+			return nil, fmt.Errorf("failed to decode provider ids: %w", err)
+		}
+	}
+	c.SystemPrompt = systemPrompt.String
+	c.CreatedBy = createdBy.String
+	return c, nil
+}
+
+func scanGoldenRun(row interface{ Scan(...interface{}) error }) (*goldenprompts.Run, error) {
+	r := &goldenprompts.Run{}
+	var results string
+	var reason sql.NullString
+	if err := row.Scan(&r.ID, &r.ProjectID, &r.Trigger, &reason, &r.Status, &r.Cases, &r.Passed, &r.Failed,
+		&r.Regressions, &results, &r.StartedAt, &r.FinishedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan golden prompt run: %w", err)
+	}
+	if err := json.Unmarshal([]byte(results), &r.Results); err != nil {
+		return nil, fmt.Errorf("failed to decode results: %w", err)
+	}
+	r.Reason = reason.String
+	return r, nil
+}
@@ -0,0 +1,82 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/issuesync"
+)
+
+const issueLinkColumns = `project_id, tracker, external_id, bead_id, url, issue_state, issue_updated_at, synced_at`
+
+// SaveIssueLink inserts or replaces the link of an imported issue
+func (d *Database) SaveIssueLink(l *issuesync.Link) error {
+	_, err := d.db.Exec(`INSERT OR REPLACE INTO issue_links (`+issueLinkColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		l.ProjectID,
+		l.Tracker,
+		l.ExternalID,
+		l.BeadID,
+		sqlNullString(l.URL),
+		l.IssueState,
+		l.IssueUpdatedAt,
+		l.SyncedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save issue link: %w", err)
+	}
+	return nil
+}
+
+// GetIssueLink returns the link of an issue, or nil if it was not imported
+func (d *Database) GetIssueLink(projectID, tracker, externalID string) (*issuesync.Link, error) {
+	l, err := scanIssueLink(d.db.QueryRow(`SELECT `+issueLinkColumns+` FROM issue_links WHERE project_id = ? AND tracker = ? AND external_id = ?`, projectID, tracker, externalID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// GetIssueLinkByBead returns the link of a bead, or nil if it was not
+// imported
+func (d *Database) GetIssueLinkByBead(beadID string) (*issuesync.Link, error) {
+	l, err := scanIssueLink(d.db.QueryRow(`SELECT `+issueLinkColumns+` FROM issue_links WHERE bead_id = ?`, beadID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, err
+}
+
+// ListIssueLinks returns a project's issue links, most recently synced
+// first
+func (d *Database) ListIssueLinks(projectID string) ([]*issuesync.Link, error) {
+	rows, err := d.db.Query(`SELECT `+issueLinkColumns+` FROM issue_links WHERE project_id = ? ORDER BY synced_at DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list issue links: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*issuesync.Link
+	for rows.Next() {
+		l, err := scanIssueLink(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, l)
+	}
+	return list, rows.Err()
+}
+
+func scanIssueLink(row interface{ Scan(...interface{}) error }) (*issuesync.Link, error) {
+	l := &issuesync.Link{}
+	var url sql.NullString
+	var issueUpdatedAt sql.NullTime
+	if err := row.Scan(&l.ProjectID, &l.Tracker, &l.ExternalID, &l.BeadID, &url, &l.IssueState, &issueUpdatedAt, &l.SyncedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan issue link: %w", err)
+	}
+	l.URL = url.String
+	l.IssueUpdatedAt = issueUpdatedAt.Time
+	return l, nil
+}
@@ -0,0 +1,175 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Key rotation statuses
+const (
+	KeyRotationStaged     = "staged"
+	KeyRotationVerified   = "verified"
+	KeyRotationFailed     = "failed"
+	KeyRotationSwapped    = "swapped"
+	KeyRotationRetired    = "retired"
+	KeyRotationRolledBack = "rolled_back"
+	KeyRotationCanceled   = "canceled"
+)
+
+// KeyRotation tracks a single provider credential rotation
+type KeyRotation struct {
+	ID          string     `json:"id"`
+	ProviderID  string     `json:"provider_id"`
+	OldKeyID    string     `json:"old_key_id,omitempty"`
+	NewKeyID    string     `json:"new_key_id"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	InitiatedBy string     `json:"initiated_by,omitempty"`
+	StagedAt    time.Time  `json:"staged_at"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	SwappedAt   *time.Time `json:"swapped_at,omitempty"`
+	RetireAt    *time.Time `json:"retire_at,omitempty"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+}
+
+const keyRotationColumns = `id, provider_id, old_key_id, new_key_id, status, error, initiated_by,
+	staged_at, verified_at, swapped_at, retire_at, retired_at`
+
+// UpsertKeyRotation inserts or updates a key rotation record
+func (d *Database) UpsertKeyRotation(kr *KeyRotation) error {
+	query := `
+		INSERT INTO provider_key_rotations (` + keyRotationColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			error = excluded.error,
+			verified_at = excluded.verified_at,
+			swapped_at = excluded.swapped_at,
+			retire_at = excluded.retire_at,
+			retired_at = excluded.retired_at
+	`
+
+	_, err := d.db.Exec(query,
+		kr.ID,
+		kr.ProviderID,
+		sqlNullString(kr.OldKeyID),
+		kr.NewKeyID,
+		kr.Status,
+		sqlNullString(kr.Error),
+		sqlNullString(kr.InitiatedBy),
+		kr.StagedAt,
+		sqlNullTime(kr.VerifiedAt),
+		sqlNullTime(kr.SwappedAt),
+		sqlNullTime(kr.RetireAt),
+		sqlNullTime(kr.RetiredAt),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert key rotation: %w", err)
+	}
+	return nil
+}
+
+// GetLatestKeyRotation returns the most recent rotation for a provider, or
+// nil if the provider has never been rotated
+func (d *Database) GetLatestKeyRotation(providerID string) (*KeyRotation, error) {
+	query := `SELECT ` + keyRotationColumns + `
+		FROM provider_key_rotations
+		WHERE provider_id = ?
+		ORDER BY staged_at DESC
+		LIMIT 1`
+
+	rows, err := d.db.Query(query, providerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get key rotation: %w", err)
+	}
+	defer rows.Close()
+
+	rotations, err := scanKeyRotations(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(rotations) == 0 {
+		return nil, nil
+	}
+	return rotations[0], nil
+}
+
+// ListKeyRotations returns the rotation history for a provider, newest first
+func (d *Database) ListKeyRotations(providerID string, limit int) ([]*KeyRotation, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `SELECT ` + keyRotationColumns + `
+		FROM provider_key_rotations
+		WHERE provider_id = ?
+		ORDER BY staged_at DESC
+		LIMIT ?`
+
+	rows, err := d.db.Query(query, providerID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key rotations: %w", err)
+	}
+	defer rows.Close()
+	return scanKeyRotations(rows)
+}
+
+// ListKeyRotationsDueForRetirement returns swapped rotations whose grace
+// period has elapsed
+func (d *Database) ListKeyRotationsDueForRetirement(now time.Time) ([]*KeyRotation, error) {
+	query := `SELECT ` + keyRotationColumns + `
+		FROM provider_key_rotations
+		WHERE status = ? AND retire_at IS NOT NULL AND retire_at <= ?
+		ORDER BY retire_at ASC`
+
+	rows, err := d.db.Query(query, KeyRotationSwapped, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key rotations due for retirement: %w", err)
+	}
+	defer rows.Close()
+	return scanKeyRotations(rows)
+}
+
+func scanKeyRotations(rows *sql.Rows) ([]*KeyRotation, error) {
+	var rotations []*KeyRotation
+	for rows.Next() {
+		kr := &KeyRotation{}
+		var oldKeyID, errMsg, initiatedBy sql.NullString
+		var verifiedAt, swappedAt, retireAt, retiredAt sql.NullTime
+
+		if err := rows.Scan(
+			&kr.ID,
+			&kr.ProviderID,
+			&oldKeyID,
+			&kr.NewKeyID,
+			&kr.Status,
+			&errMsg,
+			&initiatedBy,
+			&kr.StagedAt,
+			&verifiedAt,
+			&swappedAt,
+			&retireAt,
+			&retiredAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan key rotation: %w", err)
+		}
+
+		kr.OldKeyID = oldKeyID.String
+		kr.Error = errMsg.String
+		kr.InitiatedBy = initiatedBy.String
+		kr.VerifiedAt = nullTimePtr(verifiedAt)
+		kr.SwappedAt = nullTimePtr(swappedAt)
+		kr.RetireAt = nullTimePtr(retireAt)
+		kr.RetiredAt = nullTimePtr(retiredAt)
+		rotations = append(rotations, kr)
+	}
+	return rotations, rows.Err()
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	v := t.Time
+	return &v
+}
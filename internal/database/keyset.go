@@ -0,0 +1,44 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+)
+
+// keysetAfter builds the condition that keeps the rows sorting after a
+// keyset, the values of cols on the last row of the previous page. It is
+// the row comparison (a, b, id) > (?, ?, ?) (< when descending) spelled out
+// column by column, which both backends can use with an index and which
+// also works for computed columns such as notificationPriorityRank.
+// The caller must order by the same columns in the same direction.
+func keysetAfter(cols []string, after []interface{}, desc bool) (string, []interface{}, error) {
+	if len(after) != len(cols) {
+		return "", nil, fmt.Errorf("cursor has %d values, expected %d", len(after), len(cols))
+	}
+	op := " > ?"
+	if desc {
+		op = " < ?"
+	}
+	var terms []string
+	var args []interface{}
+	for i := range cols {
+		var term []string
+		for j := 0; j < i; j++ {
+			term = append(term, cols[j]+" = ?")
+			args = append(args, after[j])
+		}
+		term = append(term, cols[i]+op)
+		args = append(args, after[i])
+		terms = append(terms, "("+strings.Join(term, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", args, nil
+}
+
+// orderBy returns the ORDER BY clause for cols, all in one direction
+func orderBy(cols []string, desc bool) string {
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	return " ORDER BY " + strings.Join(cols, dir+", ") + dir
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jordanhubbard/loom/internal/knowledge"
+)
+
+// SaveKnowledgeEdges stores knowledge graph edges in one transaction,
+// ignoring ones already stored
+func (d *Database) SaveKnowledgeEdges(edges []*knowledge.Edge) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := d.rebind(`
+		INSERT INTO knowledge_edges (project_id, from_kind, from_id, to_kind, to_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (project_id, from_kind, from_id, to_kind, to_id) DO NOTHING
+	`)
+	for _, e := range edges {
+		createdAt := e.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		if _, err := tx.Exec(insert, e.ProjectID, e.From.Kind, e.From.ID, e.To.Kind, e.To.ID, createdAt); err != nil {
+			return fmt.Errorf("failed to save knowledge edge: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListKnowledgeEdges returns a project's knowledge graph edges with the
+// node at either end, newest first
+func (d *Database) ListKnowledgeEdges(projectID string, n knowledge.Node) ([]*knowledge.Edge, error) {
+	rows, err := d.query(`
+		SELECT project_id, from_kind, from_id, to_kind, to_id, created_at
+		FROM knowledge_edges
+		WHERE project_id = ? AND ((from_kind = ? AND from_id = ?) OR (to_kind = ? AND to_id = ?))
+		ORDER BY created_at DESC`,
+		projectID, n.Kind, n.ID, n.Kind, n.ID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list knowledge edges: %w", err)
+	}
+	defer rows.Close()
+
+	var edges []*knowledge.Edge
+	for rows.Next() {
+		e := &knowledge.Edge{}
+		if err := rows.Scan(&e.ProjectID, &e.From.Kind, &e.From.ID, &e.To.Kind, &e.To.ID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan knowledge edge: %w", err)
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
+
+// ListKnowledgeNodes returns a project's knowledge graph nodes of a kind
+// whose IDs start with prefix
+func (d *Database) ListKnowledgeNodes(projectID, kind, prefix string) ([]knowledge.Node, error) {
+	// substr rather than LIKE, so _ and % in paths match only themselves
+	rows, err := d.query(`
+		SELECT to_id FROM knowledge_edges
+		WHERE project_id = ? AND to_kind = ? AND substr(to_id, 1, ?) = ?
+		UNION
+		SELECT from_id FROM knowledge_edges
+		WHERE project_id = ? AND from_kind = ? AND substr(from_id, 1, ?) = ?`,
+		projectID, kind, utf8.RuneCountInString(prefix), prefix,
+		projectID, kind, utf8.RuneCountInString(prefix), prefix,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list knowledge nodes: %w", err)
+	}
+	defer rows.Close()
+
+	var nodes []knowledge.Node
+	for rows.Next() {
+		n := knowledge.Node{Kind: kind}
+		if err := rows.Scan(&n.ID); err != nil {
+			return nil, fmt.Errorf("failed to scan knowledge node: %w", err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, rows.Err()
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// lessonEffectivenessScore is the relevance score of a lesson that went
+// into injections dispatches, successes of which succeeded: 0.5 plus its
+// success rate smoothed toward one half, so a lesson never injected keeps
+// the default of 1.0 and one score moves with each outcome less as more are
+// recorded. Time decay still applies on top when lessons are read.
+func lessonEffectivenessScore(injections, successes int) float64 {
+	return 0.5 + float64(successes+1)/float64(injections+2)
+}
+
+// RecordLessonInjections stores the lessons put into dispatches' prompts
+// with how the dispatches ended, replacing the outcome recorded for a
+// dispatch before, and rescores each lesson by its dispatches' outcomes
+func (d *Database) RecordLessonInjections(injections []*models.LessonInjection) error {
+	if len(injections) == 0 {
+		return nil
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insert := d.rebind(`
+		INSERT INTO lesson_injections (lesson_id, dispatch_id, project_id, bead_id, outcome, succeeded, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (lesson_id, dispatch_id) DO UPDATE SET outcome = excluded.outcome, succeeded = excluded.succeeded
+	`)
+	var lessonIDs []string
+	seen := make(map[string]bool)
+	for _, in := range injections {
+		createdAt := in.CreatedAt
+		if createdAt.IsZero() {
+			createdAt = time.Now()
+		}
+		succeeded := 0
+		if in.Succeeded {
+			succeeded = 1
+		}
+		if _, err := tx.Exec(insert, in.LessonID, in.DispatchID, in.ProjectID, in.BeadID, in.Outcome, succeeded, createdAt); err != nil {
+			return fmt.Errorf("failed to record lesson injection: %w", err)
+		}
+		if !seen[in.LessonID] {
+			seen[in.LessonID] = true
+			lessonIDs = append(lessonIDs, in.LessonID)
+		}
+	}
+
+	count := d.rebind(`SELECT COUNT(*), COALESCE(SUM(succeeded), 0) FROM lesson_injections WHERE lesson_id = ?`)
+	update := d.rebind(`UPDATE lessons SET relevance_score = ? WHERE id = ?`)
+	for _, id := range lessonIDs {
+		var n, s int
+		if err := tx.QueryRow(count, id).Scan(&n, &s); err != nil {
+			return fmt.Errorf("failed to count lesson injections: %w", err)
+		}
+		if _, err := tx.Exec(update, lessonEffectivenessScore(n, s), id); err != nil {
+			return fmt.Errorf("failed to rescore lesson: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// LessonEffectivenessReport compares the success rate of the dispatches
+// each of a project's lessons went into with that of all its dispatches
+// with lessons recorded. Lessons injected fewer than minInjections times
+// are left out; up to limit lessons are listed as helpful, most lift first,
+// and as useless, least lift first.
+func (d *Database) LessonEffectivenessReport(projectID string, minInjections, limit int) (*models.LessonEffectivenessReport, error) {
+	if minInjections <= 0 {
+		minInjections = 3
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+	report := &models.LessonEffectivenessReport{
+		ProjectID:     projectID,
+		MinInjections: minInjections,
+		Helpful:       []*models.LessonEffectiveness{},
+		Useless:       []*models.LessonEffectiveness{},
+	}
+
+	var succeeded int
+	err := d.queryRow(`
+		SELECT COUNT(*), COALESCE(SUM(succeeded), 0)
+		FROM (SELECT dispatch_id, MAX(succeeded) AS succeeded FROM lesson_injections WHERE project_id = ? GROUP BY dispatch_id) AS dispatches`,
+		projectID,
+	).Scan(&report.Dispatches, &succeeded)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count dispatches with lessons: %w", err)
+	}
+	if report.Dispatches == 0 {
+		return report, nil
+	}
+	report.BaselineSuccessRate = float64(succeeded) / float64(report.Dispatches)
+
+	rows, err := d.query(`
+		SELECT lesson_id, COUNT(*), COALESCE(SUM(succeeded), 0)
+		FROM lesson_injections
+		WHERE project_id = ?
+		GROUP BY lesson_id
+		HAVING COUNT(*) >= ?`,
+		projectID, minInjections,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lesson injections: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*models.LessonEffectiveness)
+	var ids []string
+	for rows.Next() {
+		var id string
+		e := &models.LessonEffectiveness{}
+		if err := rows.Scan(&id, &e.Injections, &e.Successes); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson injections: %w", err)
+		}
+		e.SuccessRate = float64(e.Successes) / float64(e.Injections)
+		e.Lift = e.SuccessRate - report.BaselineSuccessRate
+		byID[id] = e
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Lessons deleted since they were injected are left out
+	lessons, err := d.GetLessonsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	var ranked []*models.LessonEffectiveness
+	for _, l := range lessons {
+		e := byID[l.ID]
+		e.Lesson = l
+		ranked = append(ranked, e)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		if ranked[i].Lift != ranked[j].Lift {
+			return ranked[i].Lift > ranked[j].Lift
+		}
+		return ranked[i].Injections > ranked[j].Injections
+	})
+	for _, e := range ranked {
+		if e.Lift > 0 && len(report.Helpful) < limit {
+			report.Helpful = append(report.Helpful, e)
+		}
+	}
+	for i := len(ranked) - 1; i >= 0; i-- {
+		if e := ranked[i]; e.Lift <= 0 && len(report.Useless) < limit {
+			report.Useless = append(report.Useless, e)
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,316 @@
+package database
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/memory"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// LessonSearchConfig bounds the cost of searching lessons by similarity.
+// Candidates are read in pages, each compared in one batch and then
+// dropped, so memory stays flat however many lessons a project has.
+// Zero values use the defaults.
+type LessonSearchConfig struct {
+	PageSize      int     // Lessons read from the database at a time; default 500
+	MaxCandidates int     // Newest lessons compared per search; 0 compares them all
+	ScanRate      float64 // Lessons one search reads per second; 0 is unlimited
+	MaxScans      int     // Searches reading lessons at once; default 4
+}
+
+func (c LessonSearchConfig) withDefaults() LessonSearchConfig {
+	if c.PageSize <= 0 {
+		c.PageSize = 500
+	}
+	if c.MaxScans <= 0 {
+		c.MaxScans = 4
+	}
+	return c
+}
+
+// SetLessonSearchConfig sets how lesson searches read their candidates.
+// Searches already running keep the settings they started with.
+func (d *Database) SetLessonSearchConfig(cfg LessonSearchConfig) {
+	cfg = cfg.withDefaults()
+	d.searchMu.Lock()
+	defer d.searchMu.Unlock()
+	d.lessonSearch = cfg
+	d.lessonScans = make(chan struct{}, cfg.MaxScans)
+}
+
+// lessonSearchSettings returns the search settings and the semaphore
+// bounding concurrent scans
+func (d *Database) lessonSearchSettings() (LessonSearchConfig, chan struct{}) {
+	d.searchMu.Lock()
+	defer d.searchMu.Unlock()
+	if d.lessonScans == nil {
+		d.lessonSearch = d.lessonSearch.withDefaults()
+		d.lessonScans = make(chan struct{}, d.lessonSearch.MaxScans)
+	}
+	return d.lessonSearch, d.lessonScans
+}
+
+// rankedLesson is a candidate lesson's score, kept until the winners are
+// loaded
+type rankedLesson struct {
+	id        string
+	score     float32
+	relevance float64 // Decayed by age
+}
+
+// topLessons is a min-heap of the best candidates seen so far
+type topLessons []rankedLesson
+
+func (t topLessons) Len() int            { return len(t) }
+func (t topLessons) Less(i, j int) bool  { return t[i].score < t[j].score }
+func (t topLessons) Swap(i, j int)       { t[i], t[j] = t[j], t[i] }
+func (t *topLessons) Push(x interface{}) { *t = append(*t, x.(rankedLesson)) }
+func (t *topLessons) Pop() interface{} {
+	old := *t
+	last := old[len(old)-1]
+	*t = old[:len(old)-1]
+	return last
+}
+
+// offer keeps r if it is among the best k seen
+func (t *topLessons) offer(r rankedLesson, k int) {
+	if t.Len() < k {
+		heap.Push(t, r)
+	} else if r.score > (*t)[0].score {
+		(*t)[0] = r
+		heap.Fix(t, 0)
+	}
+}
+
+// best returns the kept candidates, best first
+func (t *topLessons) best() []rankedLesson {
+	out := make([]rankedLesson, t.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(t).(rankedLesson)
+	}
+	return out
+}
+
+// lessonPage is a page of candidate lessons' ranking columns
+type lessonPage struct {
+	ids        []string
+	relevances []float64
+	embeddings [][]float32
+	versions   []string
+	createdAt  []time.Time
+}
+
+// scoreLessons combines each lesson's cosine similarity to the query of
+// its embedding version with its relevance decayed by age, comparing the
+// lessons of each query in one batch. Lessons without an embedding or a
+// query of their version score a flat 0.1, so they still appear when too
+// few embedded lessons do.
+func scoreLessons(page *lessonPage, queries map[string][]float32, offer func(rankedLesson)) {
+	byQuery := make(map[string][]int)
+	for i, version := range page.versions {
+		key := version
+		if _, ok := queries[key]; !ok {
+			key = ""
+		}
+		if len(queries[key]) == 0 || len(page.embeddings[i]) == 0 {
+			offer(rankedLesson{id: page.ids[i], score: 0.1, relevance: page.relevances[i]})
+			continue
+		}
+		byQuery[key] = append(byQuery[key], i)
+	}
+	var vecs [][]float32
+	var sims []float32
+	for key, indices := range byQuery {
+		vecs, sims = vecs[:0], sims[:0]
+		for _, i := range indices {
+			vecs = append(vecs, page.embeddings[i])
+			sims = append(sims, 0)
+		}
+		memory.CosineSimilarities(queries[key], vecs, sims)
+		for j, i := range indices {
+			offer(rankedLesson{
+				id:        page.ids[i],
+				score:     float32(page.relevances[i])*0.3 + sims[j]*0.7,
+				relevance: page.relevances[i],
+			})
+		}
+	}
+}
+
+// readLessonPage reads the ranking columns of up to limit lessons matching
+// where, newest first, older than the last lesson of the previous page
+// when there is one
+func (d *Database) readLessonPage(where string, args []interface{}, prev *lessonPage, limit int, now time.Time) (*lessonPage, error) {
+	query := `SELECT id, relevance_score, created_at, embedding, embedding_version FROM lessons WHERE ` + where
+	pageArgs := append([]interface{}{}, args...)
+	if prev != nil && len(prev.ids) > 0 {
+		last := len(prev.ids) - 1
+		query += ` AND (created_at < ? OR (created_at = ? AND id < ?))`
+		pageArgs = append(pageArgs, prev.createdAt[last], prev.createdAt[last], prev.ids[last])
+	}
+	query += ` ORDER BY created_at DESC, id DESC LIMIT ?`
+	pageArgs = append(pageArgs, limit)
+
+	rows, err := d.query(query, pageArgs...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	page := &lessonPage{}
+	for rows.Next() {
+		var id, version string
+		var relevance float64
+		var createdAt time.Time
+		var embBytes []byte
+		if err := rows.Scan(&id, &relevance, &createdAt, &embBytes, &version); err != nil {
+			return nil, err
+		}
+		ageDays := now.Sub(createdAt).Hours() / 24
+		page.ids = append(page.ids, id)
+		page.relevances = append(page.relevances, relevance*math.Pow(0.5, ageDays/7.0))
+		page.embeddings = append(page.embeddings, memory.DecodeEmbedding(embBytes))
+		page.versions = append(page.versions, version)
+		page.createdAt = append(page.createdAt, createdAt)
+	}
+	return page, rows.Err()
+}
+
+// searchLessons ranks the lessons matching where against the queries, as
+// SearchLessonsByEmbeddings describes. Candidates are read a page at a
+// time, newest first, and only the winners are loaded in full.
+func (d *Database) searchLessons(where string, args []interface{}, queries map[string][]float32, topK int) ([]*models.Lesson, error) {
+	if topK <= 0 {
+		topK = 5
+	}
+	cfg, scans := d.lessonSearchSettings()
+	scans <- struct{}{}
+	defer func() { <-scans }()
+
+	top := &topLessons{}
+	offer := func(r rankedLesson) { top.offer(r, topK) }
+	now := time.Now()
+	read := 0
+	var page *lessonPage
+	for {
+		limit := cfg.PageSize
+		if cfg.MaxCandidates > 0 {
+			limit = min(limit, cfg.MaxCandidates-read)
+		}
+		if limit <= 0 {
+			break
+		}
+		var err error
+		if page, err = d.readLessonPage(where, args, page, limit, now); err != nil {
+			return nil, err
+		}
+		read += len(page.ids)
+		scoreLessons(page, queries, offer)
+		if len(page.ids) < limit {
+			break
+		}
+		if cfg.ScanRate > 0 {
+			time.Sleep(time.Duration(float64(len(page.ids)) / cfg.ScanRate * float64(time.Second)))
+		}
+	}
+	return d.loadRankedLessons(top.best())
+}
+
+// loadRankedLessons loads ranked lessons in full, in rank order, with
+// their relevance decayed by age
+func (d *Database) loadRankedLessons(ranked []rankedLesson) ([]*models.Lesson, error) {
+	ids := make([]string, len(ranked))
+	for i, r := range ranked {
+		ids[i] = r.id
+	}
+	lessons, err := d.GetLessonsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	relevance := make(map[string]float64, len(ranked))
+	for _, r := range ranked {
+		relevance[r.id] = r.relevance
+	}
+	for _, l := range lessons {
+		l.RelevanceScore = relevance[l.ID]
+	}
+	return lessons, nil
+}
+
+// RankLessons ranks the given lessons against the queries as
+// SearchLessonsByEmbeddings does, such as the candidates an index found,
+// and returns the top-K. Superseded lessons are left out.
+func (d *Database) RankLessons(ids []string, queries map[string][]float32, topK int) ([]*models.Lesson, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if topK <= 0 {
+		topK = 5
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	page, err := d.readLessonPage("id IN ("+placeholders+") AND superseded_by = ''", args, nil, len(ids), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank lessons: %w", err)
+	}
+	top := &topLessons{}
+	scoreLessons(page, queries, func(r rankedLesson) { top.offer(r, topK) })
+	return d.loadRankedLessons(top.best())
+}
+
+// ListLessonEmbeddingVersions returns the embedding version of each of a
+// project's embedded lessons that have not been superseded, by lesson ID
+func (d *Database) ListLessonEmbeddingVersions(projectID string) (map[string]string, error) {
+	rows, err := d.query(`
+		SELECT id, embedding_version FROM lessons
+		WHERE project_id = ? AND superseded_by = '' AND embedding IS NOT NULL`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lesson embedding versions: %w", err)
+	}
+	defer rows.Close()
+	versions := make(map[string]string)
+	for rows.Next() {
+		var id, version string
+		if err := rows.Scan(&id, &version); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson embedding version: %w", err)
+		}
+		versions[id] = version
+	}
+	return versions, rows.Err()
+}
+
+// GetLessonEmbeddings returns the embeddings of the given lessons, by ID;
+// lessons without one are left out
+func (d *Database) GetLessonEmbeddings(ids []string) (map[string][]float32, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := d.query(`SELECT id, embedding FROM lessons WHERE id IN (`+placeholders+`) AND embedding IS NOT NULL`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lesson embeddings: %w", err)
+	}
+	defer rows.Close()
+	embeddings := make(map[string][]float32, len(ids))
+	for rows.Next() {
+		var id string
+		var embBytes []byte
+		if err := rows.Scan(&id, &embBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson embedding: %w", err)
+		}
+		if vec := memory.DecodeEmbedding(embBytes); len(vec) > 0 {
+			embeddings[id] = vec
+		}
+	}
+	return embeddings, rows.Err()
+}
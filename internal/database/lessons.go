@@ -1,57 +1,16 @@
 package database
 
 import (
+	"database/sql"
 	"fmt"
 	"math"
-	"sort"
+	"strings"
 	"time"
 
 	"github.com/jordanhubbard/loom/internal/memory"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
-// migrateLessons creates the lessons table if it doesn't exist
-// and adds the embedding column for semantic search.
-func (d *Database) migrateLessons() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS lessons (
-		id TEXT PRIMARY KEY,
-		project_id TEXT NOT NULL,
-		category TEXT NOT NULL,
-		title TEXT NOT NULL,
-		detail TEXT NOT NULL,
-		source_bead_id TEXT,
-		source_agent_id TEXT,
-		relevance_score REAL NOT NULL DEFAULT 1.0,
-		created_at DATETIME NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_lessons_project ON lessons(project_id);
-	CREATE INDEX IF NOT EXISTS idx_lessons_category ON lessons(category);
-	`
-	if _, err := d.db.Exec(schema); err != nil {
-		return err
-	}
-
-	// Add embedding column if it doesn't exist (migration)
-	_, err := d.db.Exec(`ALTER TABLE lessons ADD COLUMN embedding BLOB`)
-	if err != nil {
-		// Column already exists — ignore the error
-		if !isAlterColumnExistsError(err) {
-			return err
-		}
-	}
-	return nil
-}
-
-// isAlterColumnExistsError checks if an ALTER TABLE error is "column already exists".
-func isAlterColumnExistsError(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := err.Error()
-	return len(msg) >= 9 && msg[:9] == "duplicate"
-}
-
 // CreateLesson inserts a new lesson record.
 func (d *Database) CreateLesson(lesson *models.Lesson) error {
 	if lesson == nil {
@@ -64,26 +23,29 @@ func (d *Database) CreateLesson(lesson *models.Lesson) error {
 		lesson.RelevanceScore = 1.0
 	}
 
-	_, err := d.db.Exec(`
-		INSERT INTO lessons (id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err := d.exec(`
+		INSERT INTO lessons (id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at,
+			org_id, share_status, promoted_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		lesson.ID, lesson.ProjectID, lesson.Category, lesson.Title, lesson.Detail,
 		lesson.SourceBeadID, lesson.SourceAgentID, lesson.RelevanceScore, lesson.CreatedAt,
+		lesson.OrgID, string(lesson.ShareStatus), lesson.PromotedFrom,
 	)
 	return err
 }
 
 // GetLessonsForProject retrieves recent lessons for a project, up to limit count
 // and maxChars total detail characters. Lessons are scored with time decay.
+// Superseded lessons are left out.
 func (d *Database) GetLessonsForProject(projectID string, limit int, maxChars int) ([]*models.Lesson, error) {
 	if limit <= 0 {
 		limit = 20
 	}
 
-	rows, err := d.db.Query(`
+	rows, err := d.query(`
 		SELECT id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at
 		FROM lessons
-		WHERE project_id = ?
+		WHERE project_id = ? AND superseded_by = ''
 		ORDER BY created_at DESC
 		LIMIT ?`,
 		projectID, limit,
@@ -120,7 +82,60 @@ func (d *Database) GetLessonsForProject(projectID string, limit int, maxChars in
 	return lessons, rows.Err()
 }
 
+// GetLessonsByID returns the lessons with the given IDs, in that order,
+// skipping IDs with no lesson. Superseded lessons are included.
+func (d *Database) GetLessonsByID(ids []string) ([]*models.Lesson, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := d.query(`
+		SELECT id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding_version,
+			superseded_by, superseded_at, org_id, share_status, promoted_from
+		FROM lessons
+		WHERE id IN (`+placeholders+`)`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lessons: %w", err)
+	}
+	defer rows.Close()
+
+	byID := make(map[string]*models.Lesson, len(ids))
+	for rows.Next() {
+		l := &models.Lesson{}
+		var supersededAt sql.NullTime
+		var shareStatus string
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Category, &l.Title, &l.Detail,
+			&l.SourceBeadID, &l.SourceAgentID, &l.RelevanceScore, &l.CreatedAt, &l.EmbeddingVersion,
+			&l.SupersededBy, &supersededAt, &l.OrgID, &shareStatus, &l.PromotedFrom); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson: %w", err)
+		}
+		if supersededAt.Valid {
+			l.SupersededAt = &supersededAt.Time
+		}
+		l.ShareStatus = models.ShareStatus(shareStatus)
+		byID[l.ID] = l
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	lessons := make([]*models.Lesson, 0, len(byID))
+	for _, id := range ids {
+		if l, ok := byID[id]; ok {
+			lessons = append(lessons, l)
+		}
+	}
+	return lessons, nil
+}
+
 // StoreLessonWithEmbedding inserts a lesson along with its vector embedding.
+// The lesson's EmbeddingVersion records which embedder the vector came from.
 func (d *Database) StoreLessonWithEmbedding(lesson *models.Lesson, embedding []float32) error {
 	if lesson == nil {
 		return fmt.Errorf("lesson cannot be nil")
@@ -134,87 +149,191 @@ func (d *Database) StoreLessonWithEmbedding(lesson *models.Lesson, embedding []f
 
 	embBytes := memory.EncodeEmbedding(embedding)
 
-	_, err := d.db.Exec(`
-		INSERT INTO lessons (id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+	_, err := d.exec(`
+		INSERT INTO lessons (id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding, embedding_version,
+			org_id, share_status, promoted_from)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		lesson.ID, lesson.ProjectID, lesson.Category, lesson.Title, lesson.Detail,
 		lesson.SourceBeadID, lesson.SourceAgentID, lesson.RelevanceScore, lesson.CreatedAt, embBytes,
+		lesson.EmbeddingVersion, lesson.OrgID, string(lesson.ShareStatus), lesson.PromotedFrom,
 	)
 	return err
 }
 
 // SearchLessonsBySimilarity retrieves lessons for a project ranked by cosine
 // similarity to the query embedding. Returns the top-K most similar lessons.
-// Similarity is computed in Go — for typical lesson counts (<100) this is fast.
+// Similarity is computed in Go over pages of candidates; see
+// LessonSearchConfig for what bounds the cost in large projects.
 func (d *Database) SearchLessonsBySimilarity(projectID string, queryEmbedding []float32, topK int) ([]*models.Lesson, error) {
-	if topK <= 0 {
-		topK = 5
-	}
+	return d.SearchLessonsByEmbeddings(projectID, map[string][]float32{"": queryEmbedding}, topK)
+}
 
-	rows, err := d.db.Query(`
-		SELECT id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding
+// SearchLessonsByEmbeddings is SearchLessonsBySimilarity with the query
+// embedded once per embedding version. Each lesson is compared with the
+// query of its own version, so lessons still to be re-embedded after the
+// embedder changes stay searchable. The query under "" is compared with
+// lessons of any other version; lessons without a matching query rank as
+// if they had no embedding. Superseded lessons are left out.
+func (d *Database) SearchLessonsByEmbeddings(projectID string, queries map[string][]float32, topK int) ([]*models.Lesson, error) {
+	return d.searchLessons("project_id = ? AND superseded_by = ''", []interface{}{projectID}, queries, topK)
+}
+
+// ListLessonsToReembed returns up to limit lessons, newest first, whose
+// embedding did not come from the given embedding version, including
+// lessons stored without one
+func (d *Database) ListLessonsToReembed(version string, limit int) ([]*models.Lesson, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	rows, err := d.query(`
+		SELECT id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding_version
 		FROM lessons
-		WHERE project_id = ?
+		WHERE embedding_version <> ?
 		ORDER BY created_at DESC
-		LIMIT 200`,
-		projectID,
+		LIMIT ?`,
+		version, limit,
 	)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to list lessons to re-embed: %w", err)
 	}
 	defer rows.Close()
 
-	type scored struct {
-		lesson     *models.Lesson
-		similarity float32
+	var lessons []*models.Lesson
+	for rows.Next() {
+		l := &models.Lesson{}
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Category, &l.Title, &l.Detail,
+			&l.SourceBeadID, &l.SourceAgentID, &l.RelevanceScore, &l.CreatedAt, &l.EmbeddingVersion); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson: %w", err)
+		}
+		lessons = append(lessons, l)
 	}
+	return lessons, rows.Err()
+}
 
-	var candidates []scored
-	now := time.Now()
+// UpdateLessonEmbedding replaces a lesson's embedding and its version
+func (d *Database) UpdateLessonEmbedding(id string, embedding []float32, version string) error {
+	_, err := d.exec(`UPDATE lessons SET embedding = ?, embedding_version = ? WHERE id = ?`,
+		memory.EncodeEmbedding(embedding), version, id)
+	if err != nil {
+		return fmt.Errorf("failed to update lesson embedding: %w", err)
+	}
+	return nil
+}
 
+// CountLessonEmbeddings returns the number of lessons by embedding
+// version, with lessons that have no embedding under ""
+func (d *Database) CountLessonEmbeddings() (map[string]int, error) {
+	rows, err := d.query(`SELECT embedding_version, COUNT(*) FROM lessons GROUP BY embedding_version`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count lesson embeddings: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
 	for rows.Next() {
-		l := &models.Lesson{}
-		var embBytes []byte
-		err := rows.Scan(&l.ID, &l.ProjectID, &l.Category, &l.Title, &l.Detail,
-			&l.SourceBeadID, &l.SourceAgentID, &l.RelevanceScore, &l.CreatedAt, &embBytes)
-		if err != nil {
-			return nil, err
+		var version string
+		var n int
+		if err := rows.Scan(&version, &n); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson embedding count: %w", err)
 		}
+		counts[version] = n
+	}
+	return counts, rows.Err()
+}
 
-		// Apply time decay
-		ageDays := now.Sub(l.CreatedAt).Hours() / 24
-		l.RelevanceScore = l.RelevanceScore * math.Pow(0.5, ageDays/7.0)
+// ListLessonsWithEmbeddings returns up to limit of a project's lessons, or
+// with an empty project an organization's shared lessons, that have an
+// embedding and are not superseded, newest first, with Embedding set
+func (d *Database) ListLessonsWithEmbeddings(projectID, orgID string, limit int) ([]*models.Lesson, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	rows, err := d.query(`
+		SELECT id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding, embedding_version,
+			org_id, share_status
+		FROM lessons
+		WHERE project_id = ? AND org_id = ? AND superseded_by = '' AND embedding IS NOT NULL
+		ORDER BY created_at DESC
+		LIMIT ?`,
+		projectID, orgID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list lessons with embeddings: %w", err)
+	}
+	defer rows.Close()
 
-		embedding := memory.DecodeEmbedding(embBytes)
-		if len(embedding) == 0 || len(queryEmbedding) == 0 {
-			// No embedding — use a low default similarity so unembedded
-			// lessons still appear if there aren't enough embedded ones
-			candidates = append(candidates, scored{lesson: l, similarity: 0.1})
-			continue
+	var lessons []*models.Lesson
+	for rows.Next() {
+		l := &models.Lesson{}
+		var embBytes []byte
+		var shareStatus string
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Category, &l.Title, &l.Detail,
+			&l.SourceBeadID, &l.SourceAgentID, &l.RelevanceScore, &l.CreatedAt, &embBytes, &l.EmbeddingVersion,
+			&l.OrgID, &shareStatus); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson: %w", err)
 		}
+		l.ShareStatus = models.ShareStatus(shareStatus)
+		l.Embedding = memory.DecodeEmbedding(embBytes)
+		lessons = append(lessons, l)
+	}
+	return lessons, rows.Err()
+}
 
-		sim := memory.CosineSimilarity(queryEmbedding, embedding)
-		// Combine cosine similarity with time-decayed relevance score
-		combined := float32(l.RelevanceScore)*0.3 + sim*0.7
-		candidates = append(candidates, scored{lesson: l, similarity: combined})
+// SupersedeLesson marks a lesson as superseded by another
+func (d *Database) SupersedeLesson(id, byID string, at time.Time) error {
+	if byID == "" {
+		return fmt.Errorf("superseding lesson is required")
 	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+	_, err := d.exec(`UPDATE lessons SET superseded_by = ?, superseded_at = ? WHERE id = ?`, byID, at, id)
+	if err != nil {
+		return fmt.Errorf("failed to supersede lesson: %w", err)
 	}
+	return nil
+}
 
-	// Sort by combined similarity score descending
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].similarity > candidates[j].similarity
-	})
+// ReinstateLesson clears a lesson's superseded mark, so it is injected
+// into prompts again
+func (d *Database) ReinstateLesson(id string) error {
+	_, err := d.exec(`UPDATE lessons SET superseded_by = '', superseded_at = NULL WHERE id = ?`, id)
+	if err != nil {
+		return fmt.Errorf("failed to reinstate lesson: %w", err)
+	}
+	return nil
+}
 
-	// Return top-K
-	if len(candidates) > topK {
-		candidates = candidates[:topK]
+// ListSupersededLessons returns up to limit of a project's superseded
+// lessons, most recently superseded first
+func (d *Database) ListSupersededLessons(projectID string, limit int) ([]*models.Lesson, error) {
+	if limit <= 0 {
+		limit = 100
 	}
+	rows, err := d.query(`
+		SELECT id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding_version,
+			superseded_by, superseded_at
+		FROM lessons
+		WHERE project_id = ? AND superseded_by <> ''
+		ORDER BY superseded_at DESC
+		LIMIT ?`,
+		projectID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list superseded lessons: %w", err)
+	}
+	defer rows.Close()
 
-	result := make([]*models.Lesson, len(candidates))
-	for i, c := range candidates {
-		result[i] = c.lesson
+	var lessons []*models.Lesson
+	for rows.Next() {
+		l := &models.Lesson{}
+		var supersededAt sql.NullTime
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Category, &l.Title, &l.Detail,
+			&l.SourceBeadID, &l.SourceAgentID, &l.RelevanceScore, &l.CreatedAt, &l.EmbeddingVersion,
+			&l.SupersededBy, &supersededAt); err != nil {
+			return nil, fmt.Errorf("failed to scan lesson: %w", err)
+		}
+		if supersededAt.Valid {
+			l.SupersededAt = &supersededAt.Time
+		}
+		lessons = append(lessons, l)
 	}
-	return result, nil
+	return lessons, rows.Err()
 }
@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/migrate"
+)
+
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// postgresMigrationLock is the advisory lock key instances take while they
+// migrate, so several starting at once do not race on CREATE TABLE
+const postgresMigrationLock = 7305001
+
+// legacyColumns are the columns databases created before migrations were
+// versioned may lack: they were added to existing tables with ALTER TABLE
+// on every start. Adopting such a database adds whichever are missing so the
+// baseline migrations find the tables they create.
+var legacyColumns = []struct {
+	table, column, definition string
+}{
+	{"providers", "model", "TEXT"},
+	{"providers", "configured_model", "TEXT"},
+	{"providers", "selected_model", "TEXT"},
+	{"providers", "selection_reason", "TEXT"},
+	{"providers", "model_score", "REAL"},
+	{"providers", "selected_gpu", "TEXT"},
+	{"providers", "owner_id", "TEXT"},
+	{"providers", "is_shared", "BOOLEAN NOT NULL DEFAULT 1"},
+	{"providers", "last_heartbeat_at", "DATETIME"},
+	{"providers", "last_heartbeat_latency_ms", "INTEGER"},
+	{"providers", "last_heartbeat_error", "TEXT"},
+	{"providers", "schema_version", "TEXT DEFAULT '1.0'"},
+	{"providers", "attributes_json", "TEXT"},
+	{"providers", "cost_per_mtoken", "REAL NOT NULL DEFAULT 0"},
+	{"providers", "context_window", "INTEGER NOT NULL DEFAULT 4096"},
+	{"providers", "supports_function", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"providers", "supports_vision", "BOOLEAN NOT NULL DEFAULT 0"},
+	{"providers", "supports_streaming", "BOOLEAN NOT NULL DEFAULT 1"},
+	{"providers", "tags_json", "TEXT"},
+	{"projects", "is_sticky", "BOOLEAN"},
+	{"projects", "parent_id", "TEXT"},
+	{"projects", "closed_at", "DATETIME"},
+	{"projects", "schema_version", "TEXT DEFAULT '1.0'"},
+	{"projects", "attributes_json", "TEXT"},
+	{"projects", "git_strategy", "TEXT NOT NULL DEFAULT 'direct'"},
+	{"projects", "compliance_json", "TEXT"},
+	{"projects", "due_date", "DATETIME"},
+	{"agents", "provider_id", "TEXT"},
+	{"agents", "role", "TEXT"},
+	{"agents", "position_id", "TEXT"},
+	{"agents", "schema_version", "TEXT DEFAULT '1.0'"},
+	{"agents", "attributes_json", "TEXT"},
+	{"org_charts", "schema_version", "TEXT DEFAULT '1.0'"},
+	{"org_charts", "attributes_json", "TEXT"},
+	{"org_chart_positions", "schema_version", "TEXT DEFAULT '1.0'"},
+	{"org_chart_positions", "attributes_json", "TEXT"},
+	{"notification_preferences", "channels_json", "TEXT"},
+	{"lessons", "embedding", "BLOB"},
+}
+
+// Migrator returns the schema migrator for the database's backend
+func (d *Database) Migrator() (*migrate.Migrator, error) {
+	dir, opts := "migrations/sqlite", migrate.Options{Adopt: adoptLegacySQLite}
+	if d.dbType == "postgres" {
+		dir, opts = "migrations/postgres", migrate.Options{Postgres: true, LockKey: postgresMigrationLock}
+	}
+	migrations, err := migrate.Load(migrationFiles, dir)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.New(d.db, migrations, opts), nil
+}
+
+// Migrate applies every pending schema migration
+func (d *Database) Migrate(ctx context.Context) error {
+	m, err := d.Migrator()
+	if err != nil {
+		return err
+	}
+	applied, err := m.Up(ctx)
+	for _, mig := range applied {
+		log.Printf("Applied schema migration %04d_%s", mig.Version, mig.Name)
+	}
+	return err
+}
+
+// adoptLegacySQLite brings a SQLite database that predates versioned
+// migrations up to the baseline: the CREATE TABLE IF NOT EXISTS in the
+// first migrations leave its tables alone, so columns they gained over time
+// are added here
+func adoptLegacySQLite(ctx context.Context, conn *sql.Conn) error {
+	tables := make(map[string]bool)
+	rows, err := conn.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'table'")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		tables[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, c := range legacyColumns {
+		if !tables[c.table] {
+			continue
+		}
+		_, err := conn.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.definition))
+		if err != nil && !isAlterColumnExistsError(err) {
+			return fmt.Errorf("add %s.%s: %w", c.table, c.column, err)
+		}
+	}
+	for _, table := range []string{"providers", "projects", "agents", "org_charts", "org_chart_positions"} {
+		if tables[table] {
+			if _, err := conn.ExecContext(ctx, "UPDATE "+table+" SET schema_version = '1.0' WHERE schema_version IS NULL"); err != nil {
+				return err
+			}
+		}
+	}
+	if tables["projects"] {
+		if _, err := conn.ExecContext(ctx, "UPDATE projects SET is_sticky = 0 WHERE is_sticky IS NULL"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isAlterColumnExistsError checks if an ALTER TABLE error is "column already exists".
+func isAlterColumnExistsError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	return len(msg) >= 9 && msg[:9] == "duplicate"
+}
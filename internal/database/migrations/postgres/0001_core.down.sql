@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS request_logs;
+DROP TABLE IF EXISTS providers;
+DROP TABLE IF EXISTS instances;
+DROP TABLE IF EXISTS distributed_locks;
+DROP TABLE IF EXISTS config_kv;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS lessons;
+DROP TABLE IF EXISTS notification_preferences;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS activity_feed;
+DROP TABLE IF EXISTS users;
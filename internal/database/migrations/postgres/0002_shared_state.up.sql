@@ -0,0 +1,103 @@
+-- Tables shared between instances: users, the activity feed, notifications
+-- and their preferences, and lessons
+
+CREATE TABLE IF NOT EXISTS users (
+	id TEXT PRIMARY KEY,
+	username TEXT NOT NULL UNIQUE,
+	email TEXT,
+	role TEXT NOT NULL,
+	is_active BOOLEAN NOT NULL DEFAULT true,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+
+CREATE TABLE IF NOT EXISTS activity_feed (
+	id TEXT PRIMARY KEY,
+	event_type TEXT NOT NULL,
+	event_id TEXT,
+	timestamp TIMESTAMPTZ NOT NULL,
+	source TEXT NOT NULL,
+	actor_id TEXT,
+	actor_type TEXT,
+	project_id TEXT,
+	agent_id TEXT,
+	bead_id TEXT,
+	provider_id TEXT,
+	action TEXT NOT NULL,
+	resource_type TEXT NOT NULL,
+	resource_id TEXT NOT NULL,
+	resource_title TEXT,
+	metadata_json TEXT,
+	aggregation_key TEXT,
+	aggregation_count INTEGER DEFAULT 1,
+	is_aggregated BOOLEAN DEFAULT false,
+	visibility TEXT NOT NULL DEFAULT 'project'
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_feed_timestamp ON activity_feed(timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_project_id ON activity_feed(project_id);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_actor_id ON activity_feed(actor_id);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_event_type ON activity_feed(event_type);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_aggregation ON activity_feed(aggregation_key, timestamp DESC);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_resource_type ON activity_feed(resource_type);
+
+CREATE TABLE IF NOT EXISTS notifications (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	activity_id TEXT REFERENCES activity_feed(id) ON DELETE CASCADE,
+	event_type TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL,
+	link TEXT,
+	status TEXT NOT NULL DEFAULT 'unread',
+	priority TEXT NOT NULL DEFAULT 'normal',
+	metadata_json TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	read_at TIMESTAMPTZ,
+	archived_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_status ON notifications(status);
+CREATE INDEX IF NOT EXISTS idx_notifications_user_status ON notifications(user_id, status, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_created_at ON notifications(created_at DESC);
+
+-- Quiet hours are "HH:MM" text, as the SQLite schema stores them
+CREATE TABLE IF NOT EXISTS notification_preferences (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+	enable_in_app BOOLEAN NOT NULL DEFAULT true,
+	enable_email BOOLEAN NOT NULL DEFAULT false,
+	enable_webhook BOOLEAN NOT NULL DEFAULT false,
+	subscribed_events_json TEXT,
+	digest_mode TEXT DEFAULT 'realtime',
+	quiet_hours_start TEXT,
+	quiet_hours_end TEXT,
+	project_filters_json TEXT,
+	min_priority TEXT DEFAULT 'normal',
+	updated_at TIMESTAMPTZ NOT NULL,
+	channels_json TEXT
+);
+
+CREATE TABLE IF NOT EXISTS lessons (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	category TEXT NOT NULL,
+	title TEXT NOT NULL,
+	detail TEXT NOT NULL,
+	source_bead_id TEXT,
+	source_agent_id TEXT,
+	relevance_score DOUBLE PRECISION NOT NULL DEFAULT 1.0,
+	created_at TIMESTAMPTZ NOT NULL,
+	embedding BYTEA
+);
+
+CREATE INDEX IF NOT EXISTS idx_lessons_project ON lessons(project_id);
+CREATE INDEX IF NOT EXISTS idx_lessons_category ON lessons(category);
+
+-- Default admin user
+INSERT INTO users (id, username, email, role, is_active, created_at, updated_at)
+SELECT 'user-admin', 'admin', 'admin@loom.local', 'admin', true, now(), now()
+WHERE NOT EXISTS (SELECT 1 FROM users)
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,21 @@
+-- Creates the durable timers of the embedded scheduler, which runs the
+-- heartbeat workflows when Temporal is not used. Instances sharing the
+-- database take turns through the lease columns.
+
+CREATE TABLE IF NOT EXISTS scheduler_timers (
+	id TEXT PRIMARY KEY,
+	activity TEXT NOT NULL,
+	input_json TEXT,
+	interval_ms BIGINT NOT NULL DEFAULT 0,
+	fire_at TIMESTAMPTZ NOT NULL,
+	runs BIGINT NOT NULL DEFAULT 0,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	lease_owner TEXT,
+	lease_until TIMESTAMPTZ,
+	last_run_at TIMESTAMPTZ,
+	last_error TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduler_timers_fire_at ON scheduler_timers(fire_at);
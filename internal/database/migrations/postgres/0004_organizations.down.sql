@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_activity_feed_org;
+DROP INDEX IF EXISTS idx_users_org;
+
+ALTER TABLE activity_feed DROP COLUMN IF EXISTS org_id;
+ALTER TABLE users DROP COLUMN IF EXISTS org_id;
+ALTER TABLE providers DROP COLUMN IF EXISTS org_id;
+
+DROP TABLE IF EXISTS organizations;
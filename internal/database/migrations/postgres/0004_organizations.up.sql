@@ -0,0 +1,18 @@
+-- Creates the organizations table and places providers, users and
+-- activity in an organization. Existing rows belong to the default
+-- organization; providers without one are shared by all.
+
+CREATE TABLE IF NOT EXISTS organizations (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	daily_budget_usd DOUBLE PRECISION NOT NULL DEFAULT 0,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE providers ADD COLUMN IF NOT EXISTS org_id TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE activity_feed ADD COLUMN IF NOT EXISTS org_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_users_org ON users(org_id);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_org ON activity_feed(org_id, timestamp DESC);
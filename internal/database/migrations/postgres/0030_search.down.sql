@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS activity_feed_search ON activity_feed;
+DROP FUNCTION IF EXISTS activity_feed_search();
+DROP TABLE IF EXISTS search_documents;
@@ -0,0 +1,49 @@
+-- Creates the full-text search index over activities and beads: one row
+-- per indexed activity or bead with a generated tsvector, weighting titles
+-- above bodies. Words are not stemmed, as in SQLite: search terms match
+-- as prefixes. Triggers keep the activity feed in step with the index;
+-- beads live outside the database and are indexed by the application.
+-- Numbered to match the SQLite migration.
+
+CREATE TABLE IF NOT EXISTS search_documents (
+	id BIGSERIAL PRIMARY KEY,
+	kind TEXT NOT NULL,
+	doc_id TEXT NOT NULL,
+	org_id TEXT NOT NULL DEFAULT 'default',
+	project_id TEXT,
+	actor_id TEXT,
+	title TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	occurred_at TIMESTAMPTZ NOT NULL,
+	search_vector tsvector GENERATED ALWAYS AS (
+		setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', body), 'B')
+	) STORED,
+	UNIQUE (kind, doc_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_documents_org ON search_documents(org_id, occurred_at);
+CREATE INDEX IF NOT EXISTS idx_search_documents_vector ON search_documents USING GIN (search_vector);
+
+CREATE OR REPLACE FUNCTION activity_feed_search() RETURNS trigger AS $$
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		DELETE FROM search_documents WHERE kind = 'activity' AND doc_id = OLD.id;
+		RETURN OLD;
+	END IF;
+	INSERT INTO search_documents (kind, doc_id, org_id, project_id, actor_id, title, body, occurred_at)
+	VALUES ('activity', NEW.id, NEW.org_id, NEW.project_id, NEW.actor_id, COALESCE(NEW.resource_title, ''),
+		NEW.event_type || ' ' || NEW.action || ' ' || NEW.resource_type || ' ' || NEW.resource_id, NEW.timestamp)
+	ON CONFLICT (kind, doc_id) DO UPDATE SET title = EXCLUDED.title;
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS activity_feed_search ON activity_feed;
+CREATE TRIGGER activity_feed_search AFTER INSERT OR UPDATE OF resource_title OR DELETE ON activity_feed
+FOR EACH ROW EXECUTE FUNCTION activity_feed_search();
+
+INSERT INTO search_documents (kind, doc_id, org_id, project_id, actor_id, title, body, occurred_at)
+SELECT 'activity', id, org_id, project_id, actor_id, COALESCE(resource_title, ''),
+	event_type || ' ' || action || ' ' || resource_type || ' ' || resource_id, timestamp
+FROM activity_feed
+ON CONFLICT (kind, doc_id) DO NOTHING;
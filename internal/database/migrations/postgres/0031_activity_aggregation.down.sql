@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS activity_events;
+
+ALTER TABLE activity_feed DROP COLUMN IF EXISTS last_event_at;
@@ -0,0 +1,22 @@
+-- Tracks the latest event of each aggregated activity, so aggregation
+-- windows slide with the events joining them, and keeps the events an
+-- aggregated activity stands for in activity_events so it can be expanded.
+-- Numbered to match the SQLite migration.
+
+ALTER TABLE activity_feed ADD COLUMN IF NOT EXISTS last_event_at TIMESTAMPTZ;
+UPDATE activity_feed SET last_event_at = timestamp WHERE last_event_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS activity_events (
+	id TEXT PRIMARY KEY,
+	activity_id TEXT NOT NULL REFERENCES activity_feed(id) ON DELETE CASCADE,
+	event_id TEXT,
+	timestamp TIMESTAMPTZ NOT NULL,
+	actor_id TEXT,
+	agent_id TEXT,
+	bead_id TEXT,
+	resource_id TEXT NOT NULL DEFAULT '',
+	resource_title TEXT,
+	metadata_json TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_events_activity ON activity_events(activity_id, timestamp);
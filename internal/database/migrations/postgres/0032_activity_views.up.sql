@@ -0,0 +1,23 @@
+-- Saved activity views and the users subscribed to them. Numbered to
+-- match the SQLite migration.
+
+CREATE TABLE IF NOT EXISTS activity_views (
+	id TEXT PRIMARY KEY,
+	org_id TEXT NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	shared BOOLEAN NOT NULL DEFAULT false,
+	filters_json TEXT,
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_views_org ON activity_views(org_id, owner_id);
+
+CREATE TABLE IF NOT EXISTS activity_view_subscriptions (
+	view_id TEXT NOT NULL REFERENCES activity_views(id) ON DELETE CASCADE,
+	user_id TEXT NOT NULL,
+	min_priority TEXT NOT NULL DEFAULT 'low',
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (view_id, user_id)
+);
@@ -0,0 +1,12 @@
+-- The pricing catalog. Numbered to match the SQLite migration.
+
+CREATE TABLE IF NOT EXISTS model_prices (
+	provider_id TEXT NOT NULL DEFAULT '',
+	model TEXT NOT NULL,
+	input_per_mtoken DOUBLE PRECISION NOT NULL DEFAULT 0,
+	output_per_mtoken DOUBLE PRECISION NOT NULL DEFAULT 0,
+	cached_input_per_mtoken DOUBLE PRECISION NOT NULL DEFAULT 0,
+	source TEXT NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (provider_id, model)
+);
@@ -0,0 +1,7 @@
+-- Records the embedding model each lesson's vector came from. Numbered to
+-- match the SQLite migration.
+
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS embedding_version TEXT NOT NULL DEFAULT '';
+UPDATE lessons SET embedding_version = 'hash-256' WHERE embedding IS NOT NULL AND embedding_version = '';
+
+CREATE INDEX IF NOT EXISTS idx_lessons_embedding_version ON lessons(embedding_version);
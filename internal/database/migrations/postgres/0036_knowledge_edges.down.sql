@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_knowledge_edges_to;
+DROP TABLE IF EXISTS knowledge_edges;
@@ -0,0 +1,13 @@
+-- The knowledge graph's edges. Numbered to match the SQLite migration.
+
+CREATE TABLE IF NOT EXISTS knowledge_edges (
+	project_id TEXT NOT NULL,
+	from_kind TEXT NOT NULL,
+	from_id TEXT NOT NULL,
+	to_kind TEXT NOT NULL,
+	to_id TEXT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (project_id, from_kind, from_id, to_kind, to_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_edges_to ON knowledge_edges(project_id, to_kind, to_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_lessons_superseded_by;
+ALTER TABLE lessons DROP COLUMN IF EXISTS superseded_at;
+ALTER TABLE lessons DROP COLUMN IF EXISTS superseded_by;
@@ -0,0 +1,7 @@
+-- Records the lesson that superseded each contradicted lesson. Numbered to
+-- match the SQLite migration.
+
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS superseded_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS superseded_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_superseded_by ON lessons(project_id, superseded_by);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_lesson_injections_project;
+DROP TABLE IF EXISTS lesson_injections;
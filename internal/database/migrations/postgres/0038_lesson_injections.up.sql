@@ -0,0 +1,15 @@
+-- Lessons put into each dispatch's prompt and how the dispatch ended.
+-- Numbered to match the SQLite migration.
+
+CREATE TABLE IF NOT EXISTS lesson_injections (
+	lesson_id TEXT NOT NULL,
+	dispatch_id TEXT NOT NULL,
+	project_id TEXT NOT NULL,
+	bead_id TEXT NOT NULL DEFAULT '',
+	outcome TEXT NOT NULL,
+	succeeded INTEGER NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (lesson_id, dispatch_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_lesson_injections_project ON lesson_injections(project_id, created_at);
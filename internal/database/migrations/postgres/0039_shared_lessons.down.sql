@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_lessons_org_share;
+ALTER TABLE IF EXISTS projects DROP COLUMN IF EXISTS shared_lessons_opt_out;
+ALTER TABLE lessons DROP COLUMN IF EXISTS promoted_from;
+ALTER TABLE lessons DROP COLUMN IF EXISTS share_status;
+ALTER TABLE lessons DROP COLUMN IF EXISTS org_id;
@@ -0,0 +1,10 @@
+-- Adds the organization tier of lessons and the per-project opt-out.
+-- Numbered to match the SQLite migration.
+
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS org_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS share_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE lessons ADD COLUMN IF NOT EXISTS promoted_from TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE IF EXISTS projects ADD COLUMN IF NOT EXISTS shared_lessons_opt_out BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_org_share ON lessons(org_id, share_status);
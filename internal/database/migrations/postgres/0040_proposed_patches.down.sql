@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_proposed_patches_fingerprint;
+DROP INDEX IF EXISTS idx_proposed_patches_project;
+DROP TABLE IF EXISTS proposed_patches;
@@ -0,0 +1,22 @@
+-- Agent commits held for approval. Numbered to match the SQLite migration.
+
+CREATE TABLE IF NOT EXISTS proposed_patches (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	bead_id TEXT NOT NULL DEFAULT '',
+	agent_id TEXT NOT NULL DEFAULT '',
+	message TEXT NOT NULL DEFAULT '',
+	files_json TEXT NOT NULL,
+	diff TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	low_risk INTEGER NOT NULL DEFAULT 0,
+	decision_id TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL,
+	reviewed_by TEXT NOT NULL DEFAULT '',
+	review_reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	reviewed_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_proposed_patches_project ON proposed_patches(project_id, status, created_at);
+CREATE INDEX IF NOT EXISTS idx_proposed_patches_fingerprint ON proposed_patches(project_id, fingerprint);
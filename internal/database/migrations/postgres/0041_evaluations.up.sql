@@ -0,0 +1,41 @@
+-- Judges and their evaluations of agent output. Numbered to match the
+-- SQLite migration.
+
+CREATE TABLE IF NOT EXISTS judges (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	persona TEXT NOT NULL,
+	provider_id TEXT NOT NULL DEFAULT '',
+	prompt TEXT NOT NULL DEFAULT '',
+	criteria_json TEXT NOT NULL,
+	enabled INTEGER NOT NULL DEFAULT 1,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_judges_project ON judges(project_id);
+
+CREATE TABLE IF NOT EXISTS evaluations (
+	id TEXT PRIMARY KEY,
+	judge_id TEXT NOT NULL,
+	judge_name TEXT NOT NULL DEFAULT '',
+	project_id TEXT NOT NULL,
+	bead_id TEXT NOT NULL DEFAULT '',
+	dispatch_id TEXT NOT NULL DEFAULT '',
+	agent_id TEXT NOT NULL DEFAULT '',
+	provider_id TEXT NOT NULL,
+	model TEXT NOT NULL DEFAULT '',
+	judge_provider_id TEXT NOT NULL DEFAULT '',
+	judge_model TEXT NOT NULL DEFAULT '',
+	scores_json TEXT NOT NULL,
+	score REAL NOT NULL DEFAULT 0,
+	summary TEXT NOT NULL DEFAULT '',
+	response TEXT NOT NULL DEFAULT '',
+	error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_evaluations_project ON evaluations(project_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_evaluations_provider ON evaluations(provider_id, created_at);
@@ -0,0 +1,30 @@
+-- Scorer metrics per provider and their history. Numbered to match the
+-- SQLite migration.
+
+CREATE TABLE IF NOT EXISTS provider_metrics (
+	provider_id TEXT PRIMARY KEY,
+	model_params_b REAL NOT NULL DEFAULT 0,
+	heartbeat_latency_ms INTEGER NOT NULL DEFAULT 0,
+	avg_request_latency_ms REAL NOT NULL DEFAULT 0,
+	cost_per_mtoken REAL NOT NULL DEFAULT 0,
+	requests REAL NOT NULL DEFAULT 0,
+	failures REAL NOT NULL DEFAULT 0,
+	composite_score REAL NOT NULL DEFAULT 0,
+	recorded_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS provider_metrics_history (
+	id BIGSERIAL PRIMARY KEY,
+	provider_id TEXT NOT NULL,
+	model_params_b REAL NOT NULL DEFAULT 0,
+	heartbeat_latency_ms INTEGER NOT NULL DEFAULT 0,
+	avg_request_latency_ms REAL NOT NULL DEFAULT 0,
+	cost_per_mtoken REAL NOT NULL DEFAULT 0,
+	requests REAL NOT NULL DEFAULT 0,
+	failures REAL NOT NULL DEFAULT 0,
+	composite_score REAL NOT NULL DEFAULT 0,
+	recorded_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_metrics_history_provider ON provider_metrics_history(provider_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_provider_metrics_history_recorded ON provider_metrics_history(recorded_at);
@@ -0,0 +1,11 @@
+-- Embeddings already computed, keyed by content hash. Numbered to match the
+-- SQLite migration.
+
+CREATE TABLE IF NOT EXISTS embedding_cache (
+	cache_key TEXT PRIMARY KEY,
+	version TEXT NOT NULL,
+	embedding BYTEA NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_cache_created ON embedding_cache(created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_snoozed;
+ALTER TABLE IF EXISTS notification_preferences DROP COLUMN IF EXISTS mute_rules_json;
+ALTER TABLE IF EXISTS notifications DROP COLUMN IF EXISTS snoozed_until;
@@ -0,0 +1,7 @@
+-- Adds notification snoozes and mute rules. Numbered to match the SQLite
+-- migration.
+
+ALTER TABLE IF EXISTS notifications ADD COLUMN IF NOT EXISTS snoozed_until TIMESTAMPTZ;
+ALTER TABLE IF EXISTS notification_preferences ADD COLUMN IF NOT EXISTS mute_rules_json TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_snoozed ON notifications(status, snoozed_until);
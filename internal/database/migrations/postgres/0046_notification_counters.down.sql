@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_counters;
+ALTER TABLE IF EXISTS notifications DROP COLUMN IF EXISTS project_id;
@@ -0,0 +1,18 @@
+-- Notification projects and unread counters. Numbered to match the SQLite
+-- migration.
+
+ALTER TABLE IF EXISTS notifications ADD COLUMN IF NOT EXISTS project_id TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS notification_counters (
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id TEXT NOT NULL DEFAULT '',
+	priority TEXT NOT NULL,
+	unread INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, project_id, priority)
+);
+
+INSERT INTO notification_counters (user_id, project_id, priority, unread)
+SELECT user_id, project_id, priority, COUNT(*) FROM notifications
+WHERE status = 'unread'
+GROUP BY user_id, project_id, priority
+ON CONFLICT (user_id, project_id, priority) DO NOTHING;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_devices;
+ALTER TABLE IF EXISTS notification_preferences DROP COLUMN IF EXISTS enable_push;
@@ -0,0 +1,16 @@
+-- Push notification devices and preference. Numbered to match the SQLite
+-- migration.
+
+ALTER TABLE IF EXISTS notification_preferences ADD COLUMN IF NOT EXISTS enable_push BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS notification_devices (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	platform TEXT NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	last_seen_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_devices_user ON notification_devices(user_id);
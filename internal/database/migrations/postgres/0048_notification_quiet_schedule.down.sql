@@ -0,0 +1,3 @@
+ALTER TABLE IF EXISTS notification_preferences DROP COLUMN IF EXISTS quiet_hours_allow_critical;
+ALTER TABLE IF EXISTS notification_preferences DROP COLUMN IF EXISTS timezone;
+ALTER TABLE IF EXISTS notification_preferences DROP COLUMN IF EXISTS quiet_schedule_json;
@@ -0,0 +1,6 @@
+-- Notification quiet schedules and time zones. Numbered to match the
+-- SQLite migration.
+
+ALTER TABLE IF EXISTS notification_preferences ADD COLUMN IF NOT EXISTS quiet_schedule_json TEXT;
+ALTER TABLE IF EXISTS notification_preferences ADD COLUMN IF NOT EXISTS timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE IF EXISTS notification_preferences ADD COLUMN IF NOT EXISTS quiet_hours_allow_critical BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,33 @@
+-- Notification on-call chains and escalations. Numbered to match the
+-- SQLite migration.
+
+CREATE TABLE IF NOT EXISTS notification_oncall_chains (
+	project_id TEXT PRIMARY KEY,
+	user_ids_json TEXT NOT NULL,
+	ack_window_seconds INTEGER NOT NULL DEFAULT 0,
+	updated_by TEXT NOT NULL DEFAULT '',
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_escalations (
+	id TEXT PRIMARY KEY,
+	source_key TEXT NOT NULL UNIQUE,
+	project_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL DEFAULT '',
+	link TEXT NOT NULL DEFAULT '',
+	priority TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	notified_json TEXT NOT NULL DEFAULT '[]',
+	step INTEGER NOT NULL DEFAULT 0,
+	escalate_at TIMESTAMPTZ,
+	acknowledged_by TEXT NOT NULL DEFAULT '',
+	acknowledged_at TIMESTAMPTZ,
+	decision_id TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_escalations_due ON notification_escalations(status, escalate_at);
+CREATE INDEX IF NOT EXISTS idx_notification_escalations_project ON notification_escalations(project_id, created_at);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS command_logs;
+DROP TABLE IF EXISTS agents;
+DROP TABLE IF EXISTS org_chart_positions;
+DROP TABLE IF EXISTS org_charts;
+DROP TABLE IF EXISTS projects;
+DROP TABLE IF EXISTS providers;
+DROP TABLE IF EXISTS config_kv;
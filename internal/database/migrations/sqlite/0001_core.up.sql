@@ -0,0 +1,149 @@
+-- Core tables: configuration, providers, projects, org charts, agents and
+-- command logs
+
+-- Global configuration key-value store
+CREATE TABLE IF NOT EXISTS config_kv (
+	key TEXT PRIMARY KEY,
+	value TEXT NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+-- Global providers (shared across all projects)
+CREATE TABLE IF NOT EXISTS providers (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	type TEXT NOT NULL,
+	endpoint TEXT NOT NULL,
+	model TEXT,
+	configured_model TEXT,
+	selected_model TEXT,
+	selection_reason TEXT,
+	model_score REAL,
+	selected_gpu TEXT,
+	gpu_constraints_json TEXT,
+	description TEXT,
+	requires_key BOOLEAN NOT NULL DEFAULT 0,
+	key_id TEXT,
+	owner_id TEXT,
+	is_shared BOOLEAN NOT NULL DEFAULT 1,
+	status TEXT NOT NULL DEFAULT 'active',
+	last_heartbeat_at DATETIME,
+	last_heartbeat_latency_ms INTEGER,
+	last_heartbeat_error TEXT,
+	metrics_json TEXT,
+	schema_version TEXT NOT NULL DEFAULT '1.0',
+	attributes_json TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	cost_per_mtoken REAL NOT NULL DEFAULT 0,
+	context_window INTEGER NOT NULL DEFAULT 4096,
+	supports_function BOOLEAN NOT NULL DEFAULT 0,
+	supports_vision BOOLEAN NOT NULL DEFAULT 0,
+	supports_streaming BOOLEAN NOT NULL DEFAULT 1,
+	tags_json TEXT
+);
+
+-- Projects with hierarchy support (parent_id for sub-projects)
+CREATE TABLE IF NOT EXISTS projects (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	git_repo TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	beads_path TEXT NOT NULL,
+	parent_id TEXT,
+	is_perpetual BOOLEAN NOT NULL DEFAULT 0,
+	is_sticky BOOLEAN NOT NULL DEFAULT 0,
+	git_strategy TEXT NOT NULL DEFAULT 'direct',
+	status TEXT NOT NULL DEFAULT 'open',
+	context_json TEXT,
+	schema_version TEXT NOT NULL DEFAULT '1.0',
+	attributes_json TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	closed_at DATETIME,
+	compliance_json TEXT,
+	due_date DATETIME,
+	FOREIGN KEY (parent_id) REFERENCES projects(id) ON DELETE SET NULL
+);
+
+-- Org charts define the team structure for each project
+CREATE TABLE IF NOT EXISTS org_charts (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	is_template BOOLEAN NOT NULL DEFAULT 0,
+	parent_id TEXT,
+	schema_version TEXT NOT NULL DEFAULT '1.0',
+	attributes_json TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE,
+	FOREIGN KEY (parent_id) REFERENCES org_charts(id) ON DELETE SET NULL
+);
+
+-- Positions within an org chart (role slots)
+CREATE TABLE IF NOT EXISTS org_chart_positions (
+	id TEXT PRIMARY KEY,
+	org_chart_id TEXT NOT NULL,
+	role_name TEXT NOT NULL,
+	persona_path TEXT NOT NULL,
+	required BOOLEAN NOT NULL DEFAULT 0,
+	max_instances INTEGER NOT NULL DEFAULT 0,
+	reports_to TEXT,
+	schema_version TEXT NOT NULL DEFAULT '1.0',
+	attributes_json TEXT,
+	created_at DATETIME NOT NULL,
+	FOREIGN KEY (org_chart_id) REFERENCES org_charts(id) ON DELETE CASCADE,
+	FOREIGN KEY (reports_to) REFERENCES org_chart_positions(id) ON DELETE SET NULL
+);
+
+-- Agent instances assigned to positions
+CREATE TABLE IF NOT EXISTS agents (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	role TEXT,
+	persona_name TEXT,
+	provider_id TEXT,
+	status TEXT NOT NULL DEFAULT 'idle',
+	current_bead TEXT,
+	project_id TEXT,
+	position_id TEXT,
+	schema_version TEXT NOT NULL DEFAULT '1.0',
+	attributes_json TEXT,
+	started_at DATETIME NOT NULL,
+	last_active DATETIME NOT NULL,
+	FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE SET NULL,
+	FOREIGN KEY (position_id) REFERENCES org_chart_positions(id) ON DELETE SET NULL,
+	FOREIGN KEY (provider_id) REFERENCES providers(id) ON DELETE SET NULL
+);
+
+-- Indexes for performance
+CREATE INDEX IF NOT EXISTS idx_agents_status ON agents(status);
+CREATE INDEX IF NOT EXISTS idx_agents_project_id ON agents(project_id);
+CREATE INDEX IF NOT EXISTS idx_agents_position_id ON agents(position_id);
+CREATE INDEX IF NOT EXISTS idx_providers_status ON providers(status);
+CREATE INDEX IF NOT EXISTS idx_projects_parent_id ON projects(parent_id);
+CREATE INDEX IF NOT EXISTS idx_org_charts_project_id ON org_charts(project_id);
+CREATE INDEX IF NOT EXISTS idx_positions_org_chart_id ON org_chart_positions(org_chart_id);
+
+-- Command Logs for agent shell command execution
+CREATE TABLE IF NOT EXISTS command_logs (
+	id TEXT PRIMARY KEY,
+	agent_id TEXT NOT NULL,
+	bead_id TEXT,
+	project_id TEXT,
+	command TEXT NOT NULL,
+	working_dir TEXT NOT NULL,
+	exit_code INTEGER NOT NULL,
+	stdout TEXT,
+	stderr TEXT,
+	duration_ms INTEGER NOT NULL,
+	started_at DATETIME NOT NULL,
+	completed_at DATETIME NOT NULL,
+	context TEXT,
+	created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_command_logs_agent_id ON command_logs(agent_id);
+CREATE INDEX IF NOT EXISTS idx_command_logs_bead_id ON command_logs(bead_id);
+CREATE INDEX IF NOT EXISTS idx_command_logs_project_id ON command_logs(project_id);
+CREATE INDEX IF NOT EXISTS idx_command_logs_created_at ON command_logs(created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS milestones;
+DROP TABLE IF EXISTS motivation_triggers;
+DROP TABLE IF EXISTS motivations;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS workflow_execution_history;
+DROP TABLE IF EXISTS workflow_executions;
+DROP TABLE IF EXISTS workflow_edges;
+DROP TABLE IF EXISTS workflow_nodes;
+DROP TABLE IF EXISTS workflows;
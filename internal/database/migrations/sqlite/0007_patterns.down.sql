@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS optimizations;
+DROP TABLE IF EXISTS usage_patterns;
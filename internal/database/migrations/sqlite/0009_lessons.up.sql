@@ -0,0 +1,17 @@
+-- Creates the lessons table; embedding holds the lesson's vector for
+-- semantic search
+
+CREATE TABLE IF NOT EXISTS lessons (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	category TEXT NOT NULL,
+	title TEXT NOT NULL,
+	detail TEXT NOT NULL,
+	source_bead_id TEXT,
+	source_agent_id TEXT,
+	relevance_score REAL NOT NULL DEFAULT 1.0,
+	created_at DATETIME NOT NULL,
+	embedding BLOB
+);
+CREATE INDEX IF NOT EXISTS idx_lessons_project ON lessons(project_id);
+CREATE INDEX IF NOT EXISTS idx_lessons_category ON lessons(category);
@@ -0,0 +1,16 @@
+-- Creates the notification templates table
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+	id TEXT PRIMARY KEY,
+	org_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	channel TEXT NOT NULL,
+	title_template TEXT NOT NULL,
+	message_template TEXT NOT NULL,
+	link_template TEXT,
+	updated_by TEXT,
+	updated_at DATETIME NOT NULL,
+	UNIQUE(org_id, event_type, channel)
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_templates_org ON notification_templates(org_id);
@@ -0,0 +1,19 @@
+-- Creates the dispatch_checkpoints table used to
+-- resume in-flight action loops after a server restart
+
+CREATE TABLE IF NOT EXISTS dispatch_checkpoints (
+	bead_id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	agent_id TEXT,
+	task_id TEXT,
+	iteration INTEGER NOT NULL DEFAULT 0,
+	tokens_used INTEGER NOT NULL DEFAULT 0,
+	messages_json TEXT NOT NULL DEFAULT '[]',
+	action_log_json TEXT NOT NULL DEFAULT '[]',
+	resume_count INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispatch_checkpoints_project ON dispatch_checkpoints(project_id);
+CREATE INDEX IF NOT EXISTS idx_dispatch_checkpoints_updated ON dispatch_checkpoints(updated_at);
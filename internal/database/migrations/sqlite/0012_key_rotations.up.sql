@@ -0,0 +1,20 @@
+-- Creates the provider_key_rotations table that tracks
+-- staged, verified, swapped and retired provider credentials
+
+CREATE TABLE IF NOT EXISTS provider_key_rotations (
+	id TEXT PRIMARY KEY,
+	provider_id TEXT NOT NULL,
+	old_key_id TEXT,
+	new_key_id TEXT NOT NULL,
+	status TEXT NOT NULL,
+	error TEXT,
+	initiated_by TEXT,
+	staged_at DATETIME NOT NULL,
+	verified_at DATETIME,
+	swapped_at DATETIME,
+	retire_at DATETIME,
+	retired_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_key_rotations_provider ON provider_key_rotations(provider_id, staged_at DESC);
+CREATE INDEX IF NOT EXISTS idx_key_rotations_status ON provider_key_rotations(status, retire_at);
@@ -0,0 +1,18 @@
+-- Creates the append-only, hash-chained audit_log table
+
+CREATE TABLE IF NOT EXISTS audit_log (
+	seq INTEGER PRIMARY KEY,
+	timestamp TEXT NOT NULL,
+	actor TEXT,
+	action TEXT NOT NULL,
+	resource TEXT,
+	project_id TEXT,
+	outcome TEXT NOT NULL,
+	details TEXT,
+	prev_hash TEXT NOT NULL,
+	hash TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor, seq);
+CREATE INDEX IF NOT EXISTS idx_audit_log_timestamp ON audit_log(timestamp);
@@ -0,0 +1,16 @@
+-- Creates the agent_file_expertise table that tracks
+-- which files each agent has successfully modified
+
+CREATE TABLE IF NOT EXISTS agent_file_expertise (
+	project_id TEXT NOT NULL,
+	agent_id TEXT NOT NULL,
+	persona_name TEXT,
+	file_path TEXT NOT NULL,
+	edit_count INTEGER NOT NULL DEFAULT 0,
+	last_bead_id TEXT,
+	last_modified_at DATETIME NOT NULL,
+	PRIMARY KEY (project_id, agent_id, file_path)
+);
+
+CREATE INDEX IF NOT EXISTS idx_file_expertise_file ON agent_file_expertise(project_id, file_path);
+CREATE INDEX IF NOT EXISTS idx_file_expertise_persona ON agent_file_expertise(project_id, persona_name);
@@ -0,0 +1,21 @@
+-- Creates the api_keys table. Keys are stored as bcrypt
+-- hashes; the key value itself is never persisted.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	key_prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL,
+	scopes TEXT,
+	permissions TEXT NOT NULL,
+	is_active BOOLEAN NOT NULL DEFAULT 1,
+	expires_at DATETIME,
+	created_at DATETIME NOT NULL,
+	last_used DATETIME,
+	revoked_at DATETIME,
+	replaced_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+CREATE INDEX IF NOT EXISTS idx_api_keys_prefix ON api_keys(key_prefix);
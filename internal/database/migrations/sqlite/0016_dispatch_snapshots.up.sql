@@ -0,0 +1,25 @@
+-- Creates the dispatch_snapshots table that records
+-- what each dispatch changed so it can be rolled back
+
+CREATE TABLE IF NOT EXISTS dispatch_snapshots (
+	id TEXT PRIMARY KEY,
+	bead_id TEXT NOT NULL,
+	project_id TEXT NOT NULL,
+	agent_id TEXT,
+	dispatch_number INTEGER NOT NULL DEFAULT 0,
+	bead_status TEXT NOT NULL,
+	bead_assigned_to TEXT,
+	bead_context_json TEXT NOT NULL DEFAULT '{}',
+	worktree_json TEXT,
+	commits_json TEXT NOT NULL DEFAULT '[]',
+	branches_json TEXT NOT NULL DEFAULT '[]',
+	pr_number INTEGER NOT NULL DEFAULT 0,
+	pr_url TEXT,
+	status TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	completed_at DATETIME,
+	reverted_at DATETIME,
+	reverted_by TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispatch_snapshots_bead ON dispatch_snapshots(bead_id, created_at);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS run_artifacts;
+DROP TABLE IF EXISTS artifact_blobs;
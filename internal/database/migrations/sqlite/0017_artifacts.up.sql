@@ -0,0 +1,35 @@
+-- Creates the content-addressed artifact_blobs table and the
+-- run_artifacts index into it. Both are write-once: triggers reject any
+-- update or delete so recorded artifacts stay verifiable.
+
+CREATE TABLE IF NOT EXISTS artifact_blobs (
+	digest TEXT PRIMARY KEY,
+	size INTEGER NOT NULL,
+	content BLOB NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS run_artifacts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	dispatch_id TEXT NOT NULL,
+	bead_id TEXT,
+	project_id TEXT,
+	kind TEXT NOT NULL,
+	name TEXT,
+	iteration INTEGER NOT NULL DEFAULT 0,
+	digest TEXT NOT NULL REFERENCES artifact_blobs(digest),
+	size INTEGER NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_run_artifacts_dispatch ON run_artifacts(dispatch_id, id);
+CREATE INDEX IF NOT EXISTS idx_run_artifacts_digest ON run_artifacts(digest);
+
+CREATE TRIGGER IF NOT EXISTS artifact_blobs_no_update BEFORE UPDATE ON artifact_blobs
+BEGIN SELECT RAISE(ABORT, 'artifact blobs are immutable'); END;
+CREATE TRIGGER IF NOT EXISTS artifact_blobs_no_delete BEFORE DELETE ON artifact_blobs
+BEGIN SELECT RAISE(ABORT, 'artifact blobs are immutable'); END;
+CREATE TRIGGER IF NOT EXISTS run_artifacts_no_update BEFORE UPDATE ON run_artifacts
+BEGIN SELECT RAISE(ABORT, 'run artifacts are immutable'); END;
+CREATE TRIGGER IF NOT EXISTS run_artifacts_no_delete BEFORE DELETE ON run_artifacts
+BEGIN SELECT RAISE(ABORT, 'run artifacts are immutable'); END;
@@ -0,0 +1,34 @@
+-- Creates the event_webhooks subscription table and the
+-- webhook_deliveries log of what was sent to each subscriber
+
+CREATE TABLE IF NOT EXISTS event_webhooks (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	event_types_json TEXT NOT NULL,
+	project_ids_json TEXT,
+	enabled BOOLEAN NOT NULL DEFAULT 1,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id TEXT PRIMARY KEY,
+	subscription_id TEXT NOT NULL,
+	event_id TEXT,
+	event_type TEXT NOT NULL,
+	project_id TEXT,
+	payload TEXT NOT NULL,
+	status TEXT NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	response_code INTEGER NOT NULL DEFAULT 0,
+	error TEXT,
+	next_attempt_at DATETIME,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status);
@@ -0,0 +1,45 @@
+-- Creates the report_schedules table and the
+-- report_runs log of each generation and delivery
+
+CREATE TABLE IF NOT EXISTS report_schedules (
+	id TEXT PRIMARY KEY,
+	org_id TEXT NOT NULL DEFAULT 'default',
+	name TEXT NOT NULL,
+	report_type TEXT NOT NULL,
+	format TEXT NOT NULL,
+	params_json TEXT,
+	frequency TEXT NOT NULL,
+	at TEXT NOT NULL,
+	weekday INTEGER NOT NULL DEFAULT 0,
+	day_of_month INTEGER NOT NULL DEFAULT 0,
+	timezone TEXT NOT NULL,
+	destinations_json TEXT NOT NULL,
+	enabled BOOLEAN NOT NULL DEFAULT 1,
+	next_run_at DATETIME,
+	last_run_at DATETIME,
+	last_status TEXT,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS report_runs (
+	id TEXT PRIMARY KEY,
+	schedule_id TEXT NOT NULL,
+	org_id TEXT NOT NULL,
+	report_type TEXT NOT NULL,
+	format TEXT NOT NULL,
+	trigger_type TEXT NOT NULL,
+	status TEXT NOT NULL,
+	period_start DATETIME NOT NULL,
+	period_end DATETIME NOT NULL,
+	filename TEXT,
+	size_bytes INTEGER NOT NULL DEFAULT 0,
+	destinations_json TEXT,
+	error TEXT,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_schedules_org ON report_schedules(org_id);
+CREATE INDEX IF NOT EXISTS idx_report_runs_schedule ON report_runs(schedule_id, started_at);
@@ -0,0 +1,12 @@
+-- Creates the policy_versions table of each project's
+-- published arbiter policies
+
+CREATE TABLE IF NOT EXISTS policy_versions (
+	project_id TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	document_json TEXT NOT NULL,
+	comment TEXT,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (project_id, version)
+);
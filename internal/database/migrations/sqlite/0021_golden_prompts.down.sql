@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS golden_prompt_runs;
+DROP TABLE IF EXISTS golden_prompt_cases;
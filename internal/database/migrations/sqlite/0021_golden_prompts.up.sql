@@ -0,0 +1,35 @@
+-- Creates the tables of each project's golden prompt
+-- cases and the runs that checked them
+
+CREATE TABLE IF NOT EXISTS golden_prompt_cases (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	system_prompt TEXT,
+	prompt TEXT NOT NULL,
+	expectations_json TEXT NOT NULL,
+	provider_ids_json TEXT,
+	enabled INTEGER NOT NULL DEFAULT 1,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_golden_prompt_cases_project ON golden_prompt_cases(project_id);
+
+CREATE TABLE IF NOT EXISTS golden_prompt_runs (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	trigger TEXT NOT NULL,
+	reason TEXT,
+	status TEXT NOT NULL,
+	cases INTEGER NOT NULL DEFAULT 0,
+	passed INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0,
+	regressions INTEGER NOT NULL DEFAULT 0,
+	results_json TEXT NOT NULL,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_golden_prompt_runs_project ON golden_prompt_runs(project_id, started_at DESC);
@@ -0,0 +1,20 @@
+-- Creates the durable timers of the embedded scheduler, which runs the
+-- heartbeat workflows when Temporal is not used
+
+CREATE TABLE IF NOT EXISTS scheduler_timers (
+	id TEXT PRIMARY KEY,
+	activity TEXT NOT NULL,
+	input_json TEXT,
+	interval_ms INTEGER NOT NULL DEFAULT 0,
+	fire_at DATETIME NOT NULL,
+	runs INTEGER NOT NULL DEFAULT 0,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	lease_owner TEXT,
+	lease_until DATETIME,
+	last_run_at DATETIME,
+	last_error TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduler_timers_fire_at ON scheduler_timers(fire_at);
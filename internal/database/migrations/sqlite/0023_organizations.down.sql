@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_activity_feed_org;
+DROP INDEX IF EXISTS idx_users_org;
+DROP INDEX IF EXISTS idx_projects_org;
+
+ALTER TABLE activity_feed DROP COLUMN org_id;
+ALTER TABLE api_keys DROP COLUMN org_id;
+ALTER TABLE users DROP COLUMN org_id;
+ALTER TABLE providers DROP COLUMN org_id;
+ALTER TABLE projects DROP COLUMN org_id;
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,21 @@
+-- Creates the organizations table and places projects, providers, users,
+-- API keys and activity in an organization. Existing rows belong to the
+-- default organization; providers without one are shared by all.
+
+CREATE TABLE IF NOT EXISTS organizations (
+	id TEXT PRIMARY KEY,
+	name TEXT NOT NULL,
+	daily_budget_usd REAL NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+ALTER TABLE projects ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE providers ADD COLUMN org_id TEXT;
+ALTER TABLE users ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE api_keys ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE activity_feed ADD COLUMN org_id TEXT NOT NULL DEFAULT 'default';
+
+CREATE INDEX IF NOT EXISTS idx_projects_org ON projects(org_id);
+CREATE INDEX IF NOT EXISTS idx_users_org ON users(org_id);
+CREATE INDEX IF NOT EXISTS idx_activity_feed_org ON activity_feed(org_id, timestamp DESC);
@@ -0,0 +1,14 @@
+-- Creates the table of project templates, the blueprints
+-- new projects are made from
+
+CREATE TABLE IF NOT EXISTS project_templates (
+	id TEXT PRIMARY KEY,
+	org_id TEXT,
+	name TEXT NOT NULL,
+	spec_json TEXT NOT NULL,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_project_templates_org ON project_templates(org_id);
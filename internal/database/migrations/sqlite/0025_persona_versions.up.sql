@@ -0,0 +1,13 @@
+-- Creates the persona_versions table of published persona
+-- definitions: system prompt, allowed actions and model tier
+
+CREATE TABLE IF NOT EXISTS persona_versions (
+	name TEXT NOT NULL,
+	version INTEGER NOT NULL,
+	definition_json TEXT NOT NULL,
+	comment TEXT,
+	rolled_back_from INTEGER,
+	created_by TEXT,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (name, version)
+);
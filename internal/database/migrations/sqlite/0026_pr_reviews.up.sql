@@ -0,0 +1,27 @@
+-- Creates the pr_reviews table of automated pull request reviews:
+-- the reviewer's findings, verdict and whether the pull request merged
+
+CREATE TABLE IF NOT EXISTS pr_reviews (
+	id TEXT PRIMARY KEY,
+	project_id TEXT NOT NULL,
+	bead_id TEXT,
+	pr_number INTEGER NOT NULL,
+	pr_url TEXT,
+	branch TEXT NOT NULL,
+	base TEXT NOT NULL,
+	persona TEXT NOT NULL,
+	provider_id TEXT,
+	model TEXT,
+	status TEXT NOT NULL,
+	summary TEXT,
+	findings_json TEXT NOT NULL,
+	blocking INTEGER NOT NULL DEFAULT 0,
+	response TEXT,
+	merged BOOLEAN NOT NULL DEFAULT 0,
+	merge_error TEXT,
+	error TEXT,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_pr_reviews_project ON pr_reviews(project_id, started_at);
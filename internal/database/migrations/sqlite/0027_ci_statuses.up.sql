@@ -0,0 +1,16 @@
+-- Creates the ci_statuses table of the CI checks reported on bead
+-- branches: the head commit, its checks and the failed commits in a row
+
+CREATE TABLE IF NOT EXISTS ci_statuses (
+	project_id TEXT NOT NULL,
+	branch TEXT NOT NULL,
+	bead_id TEXT,
+	sha TEXT,
+	state TEXT NOT NULL,
+	checks_json TEXT NOT NULL,
+	failures_json TEXT NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (project_id, branch)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ci_statuses_bead ON ci_statuses(bead_id);
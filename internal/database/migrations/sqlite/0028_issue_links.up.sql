@@ -0,0 +1,16 @@
+-- Creates the issue_links table tying beads to the GitHub or Jira issues
+-- they were imported from, with the issue state last synced
+
+CREATE TABLE IF NOT EXISTS issue_links (
+	project_id TEXT NOT NULL,
+	tracker TEXT NOT NULL,
+	external_id TEXT NOT NULL,
+	bead_id TEXT NOT NULL,
+	url TEXT,
+	issue_state TEXT NOT NULL,
+	issue_updated_at DATETIME,
+	synced_at DATETIME NOT NULL,
+	PRIMARY KEY (project_id, tracker, external_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_issue_links_bead ON issue_links(bead_id);
@@ -0,0 +1,21 @@
+-- Creates the activity_rollups table holding the summary rows expired
+-- activity feed events are compacted into: one row per aggregation key, or
+-- per event type, project and actor for each day
+
+CREATE TABLE IF NOT EXISTS activity_rollups (
+	rollup_key TEXT PRIMARY KEY,
+	org_id TEXT NOT NULL DEFAULT 'default',
+	event_type TEXT NOT NULL,
+	project_id TEXT,
+	actor_id TEXT,
+	resource_type TEXT NOT NULL,
+	aggregation_key TEXT,
+	period_start DATETIME NOT NULL,
+	event_count INTEGER NOT NULL,
+	first_at DATETIME NOT NULL,
+	last_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_rollups_period ON activity_rollups(org_id, period_start);
+CREATE INDEX IF NOT EXISTS idx_activity_rollups_last_at ON activity_rollups(last_at);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS activity_feed_search_ad;
+DROP TRIGGER IF EXISTS activity_feed_search_au;
+DROP TRIGGER IF EXISTS activity_feed_search_ai;
+DROP TABLE IF EXISTS search_fts;
+DROP TABLE IF EXISTS search_documents;
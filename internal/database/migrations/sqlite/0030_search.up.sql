@@ -0,0 +1,53 @@
+-- Creates the full-text search index over activities and beads.
+-- search_documents holds one row per indexed activity or bead and
+-- search_fts is its FTS4 index (FTS5 needs a go-sqlite3 build tag; FTS4
+-- does not). Triggers keep the index in step with the table, and the
+-- activity feed in step with the index; beads live outside the database
+-- and are indexed by the application. Words are not stemmed: search terms
+-- match as prefixes, and the porter tokenizer would stem those too, so
+-- "deploy*" would no longer match "deployment".
+
+CREATE TABLE IF NOT EXISTS search_documents (
+	id INTEGER PRIMARY KEY,
+	kind TEXT NOT NULL,
+	doc_id TEXT NOT NULL,
+	org_id TEXT NOT NULL DEFAULT 'default',
+	project_id TEXT,
+	actor_id TEXT,
+	title TEXT NOT NULL DEFAULT '',
+	body TEXT NOT NULL DEFAULT '',
+	occurred_at DATETIME NOT NULL,
+	UNIQUE (kind, doc_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_documents_org ON search_documents(org_id, occurred_at);
+
+CREATE VIRTUAL TABLE IF NOT EXISTS search_fts USING fts4(content="search_documents", title, body, tokenize=unicode61);
+
+CREATE TRIGGER IF NOT EXISTS search_documents_bu BEFORE UPDATE ON search_documents
+BEGIN DELETE FROM search_fts WHERE docid = old.id; END;
+CREATE TRIGGER IF NOT EXISTS search_documents_bd BEFORE DELETE ON search_documents
+BEGIN DELETE FROM search_fts WHERE docid = old.id; END;
+CREATE TRIGGER IF NOT EXISTS search_documents_au AFTER UPDATE ON search_documents
+BEGIN INSERT INTO search_fts(docid, title, body) VALUES (new.id, new.title, new.body); END;
+CREATE TRIGGER IF NOT EXISTS search_documents_ai AFTER INSERT ON search_documents
+BEGIN INSERT INTO search_fts(docid, title, body) VALUES (new.id, new.title, new.body); END;
+
+CREATE TRIGGER IF NOT EXISTS activity_feed_search_ai AFTER INSERT ON activity_feed
+BEGIN
+	INSERT INTO search_documents (kind, doc_id, org_id, project_id, actor_id, title, body, occurred_at)
+	VALUES ('activity', new.id, new.org_id, new.project_id, new.actor_id, COALESCE(new.resource_title, ''),
+		new.event_type || ' ' || new.action || ' ' || new.resource_type || ' ' || new.resource_id, new.timestamp);
+END;
+CREATE TRIGGER IF NOT EXISTS activity_feed_search_au AFTER UPDATE OF resource_title ON activity_feed
+BEGIN
+	UPDATE search_documents SET title = COALESCE(new.resource_title, '')
+	WHERE kind = 'activity' AND doc_id = new.id;
+END;
+CREATE TRIGGER IF NOT EXISTS activity_feed_search_ad AFTER DELETE ON activity_feed
+BEGIN DELETE FROM search_documents WHERE kind = 'activity' AND doc_id = old.id; END;
+
+INSERT OR IGNORE INTO search_documents (kind, doc_id, org_id, project_id, actor_id, title, body, occurred_at)
+SELECT 'activity', id, org_id, project_id, actor_id, COALESCE(resource_title, ''),
+	event_type || ' ' || action || ' ' || resource_type || ' ' || resource_id, timestamp
+FROM activity_feed;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_activity_events_activity;
+DROP TABLE IF EXISTS activity_events;
+
+ALTER TABLE activity_feed DROP COLUMN last_event_at;
@@ -0,0 +1,23 @@
+-- Tracks the latest event of each aggregated activity, so aggregation
+-- windows slide with the events joining them, and keeps the events an
+-- aggregated activity stands for in activity_events so it can be expanded.
+-- Events are deleted with their activity.
+
+ALTER TABLE activity_feed ADD COLUMN last_event_at DATETIME;
+UPDATE activity_feed SET last_event_at = timestamp;
+
+CREATE TABLE IF NOT EXISTS activity_events (
+	id TEXT PRIMARY KEY,
+	activity_id TEXT NOT NULL,
+	event_id TEXT,
+	timestamp DATETIME NOT NULL,
+	actor_id TEXT,
+	agent_id TEXT,
+	bead_id TEXT,
+	resource_id TEXT NOT NULL DEFAULT '',
+	resource_title TEXT,
+	metadata_json TEXT,
+	FOREIGN KEY (activity_id) REFERENCES activity_feed(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_events_activity ON activity_events(activity_id, timestamp);
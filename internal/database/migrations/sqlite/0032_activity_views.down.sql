@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS activity_view_subscriptions;
+DROP INDEX IF EXISTS idx_activity_views_org;
+DROP TABLE IF EXISTS activity_views;
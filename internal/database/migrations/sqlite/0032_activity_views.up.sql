@@ -0,0 +1,25 @@
+-- Saved activity views: named activity feed filters a user keeps, and may
+-- share with their organization. Subscribing to a view notifies the
+-- subscriber of the activities it matches.
+
+CREATE TABLE IF NOT EXISTS activity_views (
+	id TEXT PRIMARY KEY,
+	org_id TEXT NOT NULL DEFAULT '',
+	owner_id TEXT NOT NULL,
+	name TEXT NOT NULL,
+	shared BOOLEAN NOT NULL DEFAULT 0,
+	filters_json TEXT,
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_activity_views_org ON activity_views(org_id, owner_id);
+
+CREATE TABLE IF NOT EXISTS activity_view_subscriptions (
+	view_id TEXT NOT NULL,
+	user_id TEXT NOT NULL,
+	min_priority TEXT NOT NULL DEFAULT 'low',
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (view_id, user_id),
+	FOREIGN KEY (view_id) REFERENCES activity_views(id) ON DELETE CASCADE
+);
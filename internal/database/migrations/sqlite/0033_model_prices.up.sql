@@ -0,0 +1,13 @@
+-- The pricing catalog: what each model charges per million tokens, on one
+-- provider or, with an empty provider_id, on any.
+
+CREATE TABLE IF NOT EXISTS model_prices (
+	provider_id TEXT NOT NULL DEFAULT '',
+	model TEXT NOT NULL,
+	input_per_mtoken REAL NOT NULL DEFAULT 0,
+	output_per_mtoken REAL NOT NULL DEFAULT 0,
+	cached_input_per_mtoken REAL NOT NULL DEFAULT 0,
+	source TEXT NOT NULL,
+	updated_at DATETIME NOT NULL,
+	PRIMARY KEY (provider_id, model)
+);
@@ -0,0 +1,27 @@
+-- Pre-flight dispatch cost estimates, with what each dispatch went on to
+-- cost once it finished. Finished dispatches calibrate later estimates.
+
+CREATE TABLE IF NOT EXISTS dispatch_estimates (
+	id TEXT PRIMARY KEY,
+	bead_id TEXT NOT NULL,
+	project_id TEXT NOT NULL DEFAULT '',
+	task_id TEXT NOT NULL DEFAULT '',
+	provider_id TEXT NOT NULL DEFAULT '',
+	model TEXT NOT NULL DEFAULT '',
+	complexity TEXT NOT NULL,
+	prompt_tokens INTEGER NOT NULL DEFAULT 0,
+	iterations REAL NOT NULL DEFAULT 0,
+	tokens INTEGER NOT NULL DEFAULT 0,
+	cost_usd REAL NOT NULL DEFAULT 0,
+	action TEXT NOT NULL,
+	reason TEXT NOT NULL DEFAULT '',
+	actual_tokens INTEGER NOT NULL DEFAULT 0,
+	actual_iterations INTEGER NOT NULL DEFAULT 0,
+	actual_cost_usd REAL NOT NULL DEFAULT 0,
+	created_at DATETIME NOT NULL,
+	completed_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_dispatch_estimates_task ON dispatch_estimates(task_id);
+CREATE INDEX IF NOT EXISTS idx_dispatch_estimates_bead ON dispatch_estimates(bead_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_dispatch_estimates_completed ON dispatch_estimates(completed_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_lessons_embedding_version;
+ALTER TABLE lessons DROP COLUMN embedding_version;
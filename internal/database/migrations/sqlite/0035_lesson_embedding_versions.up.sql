@@ -0,0 +1,9 @@
+-- Records the embedding model each lesson's vector came from, so vectors
+-- from different models are never compared and lessons embedded by an old
+-- model can be re-embedded. Embeddings stored before versions were
+-- recorded came from the hash embedder.
+
+ALTER TABLE lessons ADD COLUMN embedding_version TEXT NOT NULL DEFAULT '';
+UPDATE lessons SET embedding_version = 'hash-256' WHERE embedding IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_embedding_version ON lessons(embedding_version);
@@ -0,0 +1,15 @@
+-- The knowledge graph: edges from lessons and commits to the beads,
+-- agents and files they came from or touched. Nodes exist only as the
+-- ends of edges.
+
+CREATE TABLE IF NOT EXISTS knowledge_edges (
+	project_id TEXT NOT NULL,
+	from_kind TEXT NOT NULL,
+	from_id TEXT NOT NULL,
+	to_kind TEXT NOT NULL,
+	to_id TEXT NOT NULL,
+	created_at DATETIME NOT NULL,
+	PRIMARY KEY (project_id, from_kind, from_id, to_kind, to_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_knowledge_edges_to ON knowledge_edges(project_id, to_kind, to_id);
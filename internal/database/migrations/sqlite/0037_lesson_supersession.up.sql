@@ -0,0 +1,7 @@
+-- Records the lesson that superseded each lesson found to contradict a
+-- newer one. Superseded lessons are kept but no longer put in prompts.
+
+ALTER TABLE lessons ADD COLUMN superseded_by TEXT NOT NULL DEFAULT '';
+ALTER TABLE lessons ADD COLUMN superseded_at DATETIME;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_superseded_by ON lessons(project_id, superseded_by);
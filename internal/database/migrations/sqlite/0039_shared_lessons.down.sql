@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_lessons_org_share;
+ALTER TABLE projects DROP COLUMN shared_lessons_opt_out;
+ALTER TABLE lessons DROP COLUMN promoted_from;
+ALTER TABLE lessons DROP COLUMN share_status;
+ALTER TABLE lessons DROP COLUMN org_id;
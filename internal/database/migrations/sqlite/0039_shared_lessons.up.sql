@@ -0,0 +1,12 @@
+-- Adds the organization tier of lessons. A shared lesson has no project
+-- and belongs to an organization; it is proposed when promoted from a
+-- project lesson and put in the prompts of the organization's projects
+-- once approved. Projects can opt out of shared lessons.
+
+ALTER TABLE lessons ADD COLUMN org_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE lessons ADD COLUMN share_status TEXT NOT NULL DEFAULT '';
+ALTER TABLE lessons ADD COLUMN promoted_from TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE projects ADD COLUMN shared_lessons_opt_out BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_lessons_org_share ON lessons(org_id, share_status);
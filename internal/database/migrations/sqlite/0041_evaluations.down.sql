@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_evaluations_provider;
+DROP INDEX IF EXISTS idx_evaluations_project;
+DROP TABLE IF EXISTS evaluations;
+DROP INDEX IF EXISTS idx_judges_project;
+DROP TABLE IF EXISTS judges;
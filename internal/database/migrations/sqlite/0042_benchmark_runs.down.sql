@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_benchmark_runs_started;
+DROP TABLE IF EXISTS benchmark_runs;
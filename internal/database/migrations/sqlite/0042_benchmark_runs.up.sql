@@ -0,0 +1,17 @@
+-- Creates the benchmark_runs table of provider benchmarks on the golden
+-- tasks, with each provider's summary and every result
+
+CREATE TABLE IF NOT EXISTS benchmark_runs (
+	id TEXT PRIMARY KEY,
+	trigger TEXT NOT NULL,
+	created_by TEXT NOT NULL DEFAULT '',
+	tasks INTEGER NOT NULL DEFAULT 0,
+	passed INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0,
+	providers_json TEXT NOT NULL,
+	results_json TEXT NOT NULL,
+	started_at DATETIME NOT NULL,
+	finished_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_benchmark_runs_started ON benchmark_runs(started_at);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_provider_metrics_history_recorded;
+DROP INDEX IF EXISTS idx_provider_metrics_history_provider;
+DROP TABLE IF EXISTS provider_metrics_history;
+DROP TABLE IF EXISTS provider_metrics;
@@ -0,0 +1,31 @@
+-- Creates the provider_metrics table of what the scorer last measured
+-- about each provider, reloaded on startup, and the
+-- provider_metrics_history table of periodic snapshots of it
+
+CREATE TABLE IF NOT EXISTS provider_metrics (
+	provider_id TEXT PRIMARY KEY,
+	model_params_b REAL NOT NULL DEFAULT 0,
+	heartbeat_latency_ms INTEGER NOT NULL DEFAULT 0,
+	avg_request_latency_ms REAL NOT NULL DEFAULT 0,
+	cost_per_mtoken REAL NOT NULL DEFAULT 0,
+	requests REAL NOT NULL DEFAULT 0,
+	failures REAL NOT NULL DEFAULT 0,
+	composite_score REAL NOT NULL DEFAULT 0,
+	recorded_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS provider_metrics_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	provider_id TEXT NOT NULL,
+	model_params_b REAL NOT NULL DEFAULT 0,
+	heartbeat_latency_ms INTEGER NOT NULL DEFAULT 0,
+	avg_request_latency_ms REAL NOT NULL DEFAULT 0,
+	cost_per_mtoken REAL NOT NULL DEFAULT 0,
+	requests REAL NOT NULL DEFAULT 0,
+	failures REAL NOT NULL DEFAULT 0,
+	composite_score REAL NOT NULL DEFAULT 0,
+	recorded_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_provider_metrics_history_provider ON provider_metrics_history(provider_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_provider_metrics_history_recorded ON provider_metrics_history(recorded_at);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_embedding_cache_created;
+DROP TABLE IF EXISTS embedding_cache;
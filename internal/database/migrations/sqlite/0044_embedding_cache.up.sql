@@ -0,0 +1,12 @@
+-- Creates the embedding_cache table of vectors already computed, keyed by
+-- the SHA-256 of the embedding version and the text, so the same text is
+-- never sent to the embedding model twice
+
+CREATE TABLE IF NOT EXISTS embedding_cache (
+	cache_key TEXT PRIMARY KEY,
+	version TEXT NOT NULL,
+	embedding BLOB NOT NULL,
+	created_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_embedding_cache_created ON embedding_cache(created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_snoozed;
+ALTER TABLE notification_preferences DROP COLUMN mute_rules_json;
+ALTER TABLE notifications DROP COLUMN snoozed_until;
@@ -0,0 +1,7 @@
+-- Adds snoozed notifications, which come back unread at snoozed_until, and
+-- the mute rules kept with each user's notification preferences
+
+ALTER TABLE notifications ADD COLUMN snoozed_until DATETIME;
+ALTER TABLE notification_preferences ADD COLUMN mute_rules_json TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_notifications_snoozed ON notifications(status, snoozed_until);
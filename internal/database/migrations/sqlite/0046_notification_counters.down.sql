@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_counters;
+ALTER TABLE notifications DROP COLUMN project_id;
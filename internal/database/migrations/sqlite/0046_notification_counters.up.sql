@@ -0,0 +1,19 @@
+-- Adds the project of each notification and the notification_counters
+-- table of how many of each user's notifications are unread, by project and
+-- priority. The counters change with the notifications they count, so
+-- reading them never counts notifications.
+
+ALTER TABLE notifications ADD COLUMN project_id TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS notification_counters (
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id TEXT NOT NULL DEFAULT '',
+	priority TEXT NOT NULL,
+	unread INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (user_id, project_id, priority)
+);
+
+INSERT INTO notification_counters (user_id, project_id, priority, unread)
+SELECT user_id, project_id, priority, COUNT(*) FROM notifications
+WHERE status = 'unread'
+GROUP BY user_id, project_id, priority;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_devices;
+ALTER TABLE notification_preferences DROP COLUMN enable_push;
@@ -0,0 +1,17 @@
+-- Adds mobile push notifications: the enable_push preference and the
+-- notification_devices table of the FCM and APNs device tokens users
+-- register. A token belongs to one user; registering it again moves it.
+
+ALTER TABLE notification_preferences ADD COLUMN enable_push BOOLEAN NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS notification_devices (
+	id TEXT PRIMARY KEY,
+	user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	platform TEXT NOT NULL,
+	token TEXT NOT NULL UNIQUE,
+	name TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	last_seen_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_devices_user ON notification_devices(user_id);
@@ -0,0 +1,3 @@
+ALTER TABLE notification_preferences DROP COLUMN quiet_hours_allow_critical;
+ALTER TABLE notification_preferences DROP COLUMN timezone;
+ALTER TABLE notification_preferences DROP COLUMN quiet_schedule_json;
@@ -0,0 +1,7 @@
+-- Adds weekly quiet schedules to notification preferences: the time zone
+-- quiet hours are read in, the JSON list of quiet windows by weekday, and
+-- whether critical notifications come through quiet hours.
+
+ALTER TABLE notification_preferences ADD COLUMN quiet_schedule_json TEXT;
+ALTER TABLE notification_preferences ADD COLUMN timezone TEXT NOT NULL DEFAULT '';
+ALTER TABLE notification_preferences ADD COLUMN quiet_hours_allow_critical BOOLEAN NOT NULL DEFAULT 0;
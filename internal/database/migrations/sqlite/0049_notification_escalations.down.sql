@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS notification_escalations;
+DROP TABLE IF EXISTS notification_oncall_chains;
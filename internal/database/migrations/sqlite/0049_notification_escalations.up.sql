@@ -0,0 +1,35 @@
+-- Adds escalation of unacknowledged notifications: each project's on-call
+-- chain, and the notification_escalations table tracking high and critical
+-- notifications until someone acknowledges them. Notifications about the
+-- same activity share one escalation, keyed by source_key.
+
+CREATE TABLE IF NOT EXISTS notification_oncall_chains (
+	project_id TEXT PRIMARY KEY,
+	user_ids_json TEXT NOT NULL,
+	ack_window_seconds INTEGER NOT NULL DEFAULT 0,
+	updated_by TEXT NOT NULL DEFAULT '',
+	updated_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS notification_escalations (
+	id TEXT PRIMARY KEY,
+	source_key TEXT NOT NULL UNIQUE,
+	project_id TEXT NOT NULL,
+	event_type TEXT NOT NULL,
+	title TEXT NOT NULL,
+	message TEXT NOT NULL DEFAULT '',
+	link TEXT NOT NULL DEFAULT '',
+	priority TEXT NOT NULL,
+	status TEXT NOT NULL DEFAULT 'pending',
+	notified_json TEXT NOT NULL DEFAULT '[]',
+	step INTEGER NOT NULL DEFAULT 0,
+	escalate_at DATETIME,
+	acknowledged_by TEXT NOT NULL DEFAULT '',
+	acknowledged_at DATETIME,
+	decision_id TEXT NOT NULL DEFAULT '',
+	created_at DATETIME NOT NULL,
+	updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_escalations_due ON notification_escalations(status, escalate_at);
+CREATE INDEX IF NOT EXISTS idx_notification_escalations_project ON notification_escalations(project_id, created_at);
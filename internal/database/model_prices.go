@@ -0,0 +1,55 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/pricing"
+)
+
+const modelPriceColumns = `provider_id, model, input_per_mtoken, output_per_mtoken, cached_input_per_mtoken, source, updated_at`
+
+// SaveModelPrice inserts or replaces the price of a provider's model
+func (d *Database) SaveModelPrice(p *pricing.Price) error {
+	query := `
+		INSERT INTO model_prices (` + modelPriceColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (provider_id, model) DO UPDATE SET
+			input_per_mtoken = excluded.input_per_mtoken,
+			output_per_mtoken = excluded.output_per_mtoken,
+			cached_input_per_mtoken = excluded.cached_input_per_mtoken,
+			source = excluded.source,
+			updated_at = excluded.updated_at
+	`
+	_, err := d.exec(query, p.ProviderID, p.Model, p.InputPerMToken, p.OutputPerMToken, p.CachedInputPerMToken, p.Source, p.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save model price: %w", err)
+	}
+	return nil
+}
+
+// ListModelPrices returns every model price
+func (d *Database) ListModelPrices() ([]*pricing.Price, error) {
+	rows, err := d.query(`SELECT ` + modelPriceColumns + ` FROM model_prices ORDER BY model, provider_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list model prices: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*pricing.Price
+	for rows.Next() {
+		p := &pricing.Price{}
+		if err := rows.Scan(&p.ProviderID, &p.Model, &p.InputPerMToken, &p.OutputPerMToken, &p.CachedInputPerMToken, &p.Source, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan model price: %w", err)
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// DeleteModelPrice removes the price of a provider's model
+func (d *Database) DeleteModelPrice(providerID, model string) error {
+	if _, err := d.exec(`DELETE FROM model_prices WHERE provider_id = ? AND model = ?`, providerID, model); err != nil {
+		return fmt.Errorf("failed to delete model price: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// UnreadCount is how many of a user's notifications of one project and
+// priority are unread or, returned by a change to notifications, by how
+// much the change moved that number
+type UnreadCount struct {
+	UserID    string
+	ProjectID string
+	Priority  string
+	Count     int
+}
+
+// GetUnreadCounts returns a user's nonzero unread counts by project and
+// priority, read from the counters kept as notifications change
+func (d *Database) GetUnreadCounts(userID string) ([]UnreadCount, error) {
+	rows, err := d.query(`
+		SELECT project_id, priority, unread FROM notification_counters
+		WHERE user_id = ? AND unread > 0
+		ORDER BY project_id, priority`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get unread counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []UnreadCount
+	for rows.Next() {
+		c := UnreadCount{UserID: userID}
+		if err := rows.Scan(&c.ProjectID, &c.Priority, &c.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan unread count: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// setNotificationStatus moves the notifications matching where to status,
+// setting the columns in set as well, and updates the unread counters to
+// match in the same transaction. It returns how many notifications matched
+// and the changes to the counters.
+//
+// Notifications that stay unread, or stay not unread, move first, so the
+// second statement updates only those whose unreadness flips, and returns
+// them. Each statement checks a notification's status as it updates it, so
+// a notification changed concurrently is counted by one change only.
+func (d *Database) setNotificationStatus(where string, args []interface{}, status, set string, setArgs ...interface{}) (int, []UnreadCount, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	update := `UPDATE notifications SET status = ?`
+	if set != "" {
+		update += `, ` + set
+	}
+	updateArgs := append(append([]interface{}{status}, setArgs...), args...)
+	flips, delta := "status = 'unread'", -1
+	if status == "unread" {
+		flips, delta = "status <> 'unread'", 1
+	}
+
+	res, err := tx.Exec(d.rebind(update+` WHERE (`+where+`) AND NOT (`+flips+`)`), updateArgs...)
+	if err != nil {
+		return 0, nil, err
+	}
+	kept, err := res.RowsAffected()
+	if err != nil {
+		return 0, nil, err
+	}
+	matched := int(kept)
+
+	rows, err := tx.Query(d.rebind(update+` WHERE (`+where+`) AND `+flips+` RETURNING user_id, project_id, priority`), updateArgs...)
+	if err != nil {
+		return 0, nil, err
+	}
+	deltas := make(map[UnreadCount]int)
+	for rows.Next() {
+		var key UnreadCount
+		if err := rows.Scan(&key.UserID, &key.ProjectID, &key.Priority); err != nil {
+			rows.Close()
+			return 0, nil, err
+		}
+		matched++
+		deltas[key] += delta
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, err
+	}
+
+	var changes []UnreadCount
+	for key, delta := range deltas {
+		if delta == 0 {
+			continue
+		}
+		key.Count = delta
+		if err := d.addUnread(tx, key); err != nil {
+			return 0, nil, err
+		}
+		changes = append(changes, key)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		if a.ProjectID != b.ProjectID {
+			return a.ProjectID < b.ProjectID
+		}
+		return a.Priority < b.Priority
+	})
+	return matched, changes, tx.Commit()
+}
+
+// addUnread adds c.Count, which may be negative, to a user's unread count
+// for c's project and priority. Counts never drop below zero.
+func (d *Database) addUnread(tx *sql.Tx, c UnreadCount) error {
+	var err error
+	if c.Count > 0 {
+		_, err = tx.Exec(d.rebind(`
+			INSERT INTO notification_counters (user_id, project_id, priority, unread)
+			VALUES (?, ?, ?, ?)
+			ON CONFLICT(user_id, project_id, priority) DO UPDATE SET
+				unread = notification_counters.unread + excluded.unread
+		`), c.UserID, c.ProjectID, c.Priority, c.Count)
+	} else {
+		_, err = tx.Exec(d.rebind(`
+			UPDATE notification_counters
+			SET unread = CASE WHEN unread + ? < 0 THEN 0 ELSE unread + ? END
+			WHERE user_id = ? AND project_id = ? AND priority = ?
+		`), c.Count, c.Count, c.UserID, c.ProjectID, c.Priority)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update unread count: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// NotificationDevice is a phone or tablet a user registered for push
+// notifications
+type NotificationDevice struct {
+	ID         string
+	UserID     string
+	Platform   string // fcm or apns
+	Token      string
+	Name       string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+// SaveNotificationDevice registers a device token. Registering a token
+// again updates it in place, moving it to the registering user, since a
+// device that changes hands should page its new owner only.
+func (d *Database) SaveNotificationDevice(device *NotificationDevice) error {
+	_, err := d.exec(`
+		INSERT INTO notification_devices (id, user_id, platform, token, name, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(token) DO UPDATE SET
+			user_id = excluded.user_id,
+			platform = excluded.platform,
+			name = excluded.name,
+			last_seen_at = excluded.last_seen_at
+	`, device.ID, device.UserID, device.Platform, device.Token, device.Name, device.CreatedAt, device.LastSeenAt)
+	if err != nil {
+		return fmt.Errorf("failed to save notification device: %w", err)
+	}
+	return d.queryRow(`SELECT id, created_at FROM notification_devices WHERE token = ?`, device.Token).
+		Scan(&device.ID, &device.CreatedAt)
+}
+
+// ListNotificationDevices returns a user's devices, oldest first
+func (d *Database) ListNotificationDevices(userID string) ([]*NotificationDevice, error) {
+	rows, err := d.query(`
+		SELECT id, user_id, platform, token, name, created_at, last_seen_at
+		FROM notification_devices
+		WHERE user_id = ?
+		ORDER BY created_at, id`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification devices: %w", err)
+	}
+	defer rows.Close()
+
+	var devices []*NotificationDevice
+	for rows.Next() {
+		device := &NotificationDevice{}
+		if err := rows.Scan(&device.ID, &device.UserID, &device.Platform, &device.Token,
+			&device.Name, &device.CreatedAt, &device.LastSeenAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification device: %w", err)
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+// DeleteNotificationDevice removes one of a user's devices
+func (d *Database) DeleteNotificationDevice(id, userID string) error {
+	result, err := d.exec(`DELETE FROM notification_devices WHERE id = ? AND user_id = ?`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification device: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("notification device not found: %s", id)
+	}
+	return nil
+}
+
+// DeleteNotificationDeviceByToken removes the device with a token, as when
+// the push service reports the token is no longer valid
+func (d *Database) DeleteNotificationDeviceByToken(token string) error {
+	if _, err := d.exec(`DELETE FROM notification_devices WHERE token = ?`, token); err != nil {
+		return fmt.Errorf("failed to delete notification device: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OnCallChain is the order a project's unacknowledged notifications are
+// escalated in
+type OnCallChain struct {
+	ProjectID string
+	UserIDs   []string
+	AckWindow time.Duration // 0 uses the configured default
+	UpdatedBy string
+	UpdatedAt time.Time
+}
+
+// SaveOnCallChain inserts or replaces a project's on-call chain
+func (d *Database) SaveOnCallChain(chain *OnCallChain) error {
+	users, err := json.Marshal(chain.UserIDs)
+	if err != nil {
+		return err
+	}
+	_, err = d.exec(`
+		INSERT INTO notification_oncall_chains (project_id, user_ids_json, ack_window_seconds, updated_by, updated_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(project_id) DO UPDATE SET
+			user_ids_json = excluded.user_ids_json,
+			ack_window_seconds = excluded.ack_window_seconds,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`, chain.ProjectID, string(users), int64(chain.AckWindow/time.Second), chain.UpdatedBy, chain.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to save on-call chain: %w", err)
+	}
+	return nil
+}
+
+// GetOnCallChain returns a project's on-call chain, or nil when it has none
+func (d *Database) GetOnCallChain(projectID string) (*OnCallChain, error) {
+	chain := &OnCallChain{}
+	var users string
+	var seconds int64
+	err := d.queryRow(`
+		SELECT project_id, user_ids_json, ack_window_seconds, updated_by, updated_at
+		FROM notification_oncall_chains WHERE project_id = ?`, projectID).
+		Scan(&chain.ProjectID, &users, &seconds, &chain.UpdatedBy, &chain.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get on-call chain: %w", err)
+	}
+	if err := json.Unmarshal([]byte(users), &chain.UserIDs); err != nil {
+		return nil, fmt.Errorf("failed to decode on-call chain: %w", err)
+	}
+	chain.AckWindow = time.Duration(seconds) * time.Second
+	return chain, nil
+}
+
+// DeleteOnCallChain removes a project's on-call chain
+func (d *Database) DeleteOnCallChain(projectID string) error {
+	result, err := d.exec(`DELETE FROM notification_oncall_chains WHERE project_id = ?`, projectID)
+	if err != nil {
+		return fmt.Errorf("failed to delete on-call chain: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("on-call chain not found: %s", projectID)
+	}
+	return nil
+}
+
+// NotificationEscalation tracks a high or critical notification until
+// someone acknowledges it. Every notification sent for the same activity
+// shares one escalation, so any recipient acknowledging it stops it.
+type NotificationEscalation struct {
+	ID             string
+	SourceKey      string // The activity the notification is about, or the notification itself
+	ProjectID      string
+	EventType      string
+	Title          string
+	Message        string
+	Link           string
+	Priority       string
+	Status         string   // pending, acknowledged or ceo
+	Notified       []string // Users paged so far, in order
+	Step           int      // Members of the on-call chain paged so far
+	EscalateAt     *time.Time
+	AcknowledgedBy string
+	AcknowledgedAt *time.Time
+	DecisionID     string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+const escalationColumns = `id, source_key, project_id, event_type, title, message, link, priority,
+	status, notified_json, step, escalate_at, acknowledged_by, acknowledged_at, decision_id,
+	created_at, updated_at`
+
+func scanEscalation(row interface{ Scan(...interface{}) error }) (*NotificationEscalation, error) {
+	e := &NotificationEscalation{}
+	var notified string
+	var escalateAt, acknowledgedAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.SourceKey, &e.ProjectID, &e.EventType, &e.Title, &e.Message, &e.Link, &e.Priority,
+		&e.Status, &notified, &e.Step, &escalateAt, &e.AcknowledgedBy, &acknowledgedAt, &e.DecisionID,
+		&e.CreatedAt, &e.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(notified), &e.Notified); err != nil {
+		return nil, fmt.Errorf("failed to decode escalation %s: %w", e.ID, err)
+	}
+	e.EscalateAt = nullTimePtr(escalateAt)
+	e.AcknowledgedAt = nullTimePtr(acknowledgedAt)
+	return e, nil
+}
+
+// OpenNotificationEscalation starts tracking a notification for its
+// source, or adds its user to the escalation already tracking that source.
+// It returns the escalation either way.
+func (d *Database) OpenNotificationEscalation(e *NotificationEscalation, userID string) (*NotificationEscalation, error) {
+	notified, err := json.Marshal([]string{userID})
+	if err != nil {
+		return nil, err
+	}
+	_, err = d.exec(`
+		INSERT INTO notification_escalations (`+escalationColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', ?, 0, ?, '', NULL, '', ?, ?)
+		ON CONFLICT(source_key) DO NOTHING`,
+		e.ID, e.SourceKey, e.ProjectID, e.EventType, e.Title, e.Message, e.Link, e.Priority,
+		string(notified), sqlNullTime(e.EscalateAt), e.CreatedAt, e.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open notification escalation: %w", err)
+	}
+
+	existing, err := scanEscalation(d.queryRow(`SELECT `+escalationColumns+` FROM notification_escalations WHERE source_key = ?`, e.SourceKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification escalation: %w", err)
+	}
+	for _, id := range existing.Notified {
+		if id == userID {
+			return existing, nil
+		}
+	}
+	existing.Notified = append(existing.Notified, userID)
+	if _, err := d.UpdateNotificationEscalation(existing); err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+// GetNotificationEscalation returns an escalation by ID
+func (d *Database) GetNotificationEscalation(id string) (*NotificationEscalation, error) {
+	e, err := scanEscalation(d.queryRow(`SELECT `+escalationColumns+` FROM notification_escalations WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("notification escalation not found: %s", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification escalation: %w", err)
+	}
+	return e, nil
+}
+
+// NotificationEscalationFilters selects escalations. Empty fields match
+// everything.
+type NotificationEscalationFilters struct {
+	ProjectIDs []string // Any of these projects; empty means every project
+	Status     string
+	DueBy      *time.Time // Only pending escalations due at or before this time
+	Limit      int
+}
+
+// ListNotificationEscalations returns escalations, newest first or, with
+// DueBy, soonest due first
+func (d *Database) ListNotificationEscalations(f NotificationEscalationFilters) ([]*NotificationEscalation, error) {
+	var where []string
+	var args []interface{}
+	if len(f.ProjectIDs) > 0 {
+		where = append(where, "project_id IN ("+placeholders(len(f.ProjectIDs))+")")
+		for _, id := range f.ProjectIDs {
+			args = append(args, id)
+		}
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, f.Status)
+	}
+	order := "created_at DESC, id"
+	if f.DueBy != nil {
+		where = append(where, "status = 'pending' AND escalate_at IS NOT NULL AND escalate_at <= ?")
+		args = append(args, *f.DueBy)
+		order = "escalate_at, id"
+	}
+	query := `SELECT ` + escalationColumns + ` FROM notification_escalations`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY " + order
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification escalations: %w", err)
+	}
+	defer rows.Close()
+	var escalations []*NotificationEscalation
+	for rows.Next() {
+		e, err := scanEscalation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification escalation: %w", err)
+		}
+		escalations = append(escalations, e)
+	}
+	return escalations, rows.Err()
+}
+
+// UpdateNotificationEscalation saves the progress of a pending escalation.
+// An escalation acknowledged meanwhile is left alone, and it reports false.
+func (d *Database) UpdateNotificationEscalation(e *NotificationEscalation) (bool, error) {
+	notified, err := json.Marshal(e.Notified)
+	if err != nil {
+		return false, err
+	}
+	e.UpdatedAt = time.Now()
+	result, err := d.exec(`
+		UPDATE notification_escalations SET
+			status = ?, notified_json = ?, step = ?, escalate_at = ?,
+			acknowledged_by = ?, acknowledged_at = ?, decision_id = ?, updated_at = ?
+		WHERE id = ? AND status = 'pending'`,
+		e.Status, string(notified), e.Step, sqlNullTime(e.EscalateAt),
+		e.AcknowledgedBy, sqlNullTime(e.AcknowledgedAt), e.DecisionID, e.UpdatedAt, e.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to update notification escalation: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+// SetNotificationEscalationDecision records the CEO decision an escalation
+// ended in
+func (d *Database) SetNotificationEscalationDecision(id, decisionID string) error {
+	_, err := d.exec(`UPDATE notification_escalations SET decision_id = ?, updated_at = ? WHERE id = ?`, decisionID, time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to update notification escalation: %w", err)
+	}
+	return nil
+}
+
+// AcknowledgeNotificationEscalation records who acknowledged an escalation,
+// stopping it. It reports false when the escalation was already
+// acknowledged.
+func (d *Database) AcknowledgeNotificationEscalation(id, userID string, at time.Time) (bool, error) {
+	result, err := d.exec(`
+		UPDATE notification_escalations SET
+			status = 'acknowledged', acknowledged_by = ?, acknowledged_at = ?, escalate_at = NULL, updated_at = ?
+		WHERE id = ? AND status != 'acknowledged'`, userID, at, at, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to acknowledge notification escalation: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
@@ -0,0 +1,141 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// NotificationTemplate represents an organization's override of the text used
+// for a notification event on a given delivery channel
+type NotificationTemplate struct {
+	ID              string
+	OrgID           string
+	EventType       string
+	Channel         string
+	TitleTemplate   string
+	MessageTemplate string
+	LinkTemplate    string
+	UpdatedBy       string
+	UpdatedAt       time.Time
+}
+
+// UpsertNotificationTemplate inserts or replaces a notification template
+func (d *Database) UpsertNotificationTemplate(tmpl *NotificationTemplate) error {
+	query := `
+		INSERT INTO notification_templates (
+			id, org_id, event_type, channel, title_template,
+			message_template, link_template, updated_by, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(org_id, event_type, channel) DO UPDATE SET
+			title_template = excluded.title_template,
+			message_template = excluded.message_template,
+			link_template = excluded.link_template,
+			updated_by = excluded.updated_by,
+			updated_at = excluded.updated_at
+	`
+
+	_, err := d.db.Exec(query,
+		tmpl.ID,
+		tmpl.OrgID,
+		tmpl.EventType,
+		tmpl.Channel,
+		tmpl.TitleTemplate,
+		tmpl.MessageTemplate,
+		sqlNullString(tmpl.LinkTemplate),
+		sqlNullString(tmpl.UpdatedBy),
+		tmpl.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to upsert notification template: %w", err)
+	}
+	return nil
+}
+
+// GetNotificationTemplate retrieves the template for an org, event type and
+// channel. It returns nil, nil when no override exists.
+func (d *Database) GetNotificationTemplate(orgID, eventType, channel string) (*NotificationTemplate, error) {
+	query := `
+		SELECT id, org_id, event_type, channel, title_template,
+			   message_template, link_template, updated_by, updated_at
+		FROM notification_templates
+		WHERE org_id = ? AND event_type = ? AND channel = ?
+	`
+
+	tmpl, err := scanNotificationTemplate(d.db.QueryRow(query, orgID, eventType, channel))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// ListNotificationTemplates lists all template overrides for an org
+func (d *Database) ListNotificationTemplates(orgID string) ([]*NotificationTemplate, error) {
+	query := `
+		SELECT id, org_id, event_type, channel, title_template,
+			   message_template, link_template, updated_by, updated_at
+		FROM notification_templates
+		WHERE org_id = ?
+		ORDER BY event_type ASC, channel ASC
+	`
+
+	rows, err := d.db.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notification templates: %w", err)
+	}
+	defer rows.Close()
+
+	var templates []*NotificationTemplate
+	for rows.Next() {
+		tmpl, err := scanNotificationTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification template: %w", err)
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, rows.Err()
+}
+
+// DeleteNotificationTemplate removes a template override, restoring the default
+func (d *Database) DeleteNotificationTemplate(orgID, eventType, channel string) error {
+	_, err := d.db.Exec(
+		`DELETE FROM notification_templates WHERE org_id = ? AND event_type = ? AND channel = ?`,
+		orgID, eventType, channel,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification template: %w", err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanNotificationTemplate(row rowScanner) (*NotificationTemplate, error) {
+	tmpl := &NotificationTemplate{}
+	var linkTemplate, updatedBy sql.NullString
+
+	err := row.Scan(
+		&tmpl.ID,
+		&tmpl.OrgID,
+		&tmpl.EventType,
+		&tmpl.Channel,
+		&tmpl.TitleTemplate,
+		&tmpl.MessageTemplate,
+		&linkTemplate,
+		&updatedBy,
+		&tmpl.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl.LinkTemplate = linkTemplate.String
+	tmpl.UpdatedBy = updatedBy.String
+	return tmpl, nil
+}
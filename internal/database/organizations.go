@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+const organizationColumns = `id, name, daily_budget_usd, created_at, updated_at`
+
+// UpsertOrganization inserts or updates an organization
+func (d *Database) UpsertOrganization(o *org.Organization) error {
+	_, err := d.exec(`
+		INSERT INTO organizations (`+organizationColumns+`)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			daily_budget_usd = excluded.daily_budget_usd,
+			updated_at = excluded.updated_at
+	`, o.ID, o.Name, o.DailyBudgetUSD, o.CreatedAt, o.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to upsert organization: %w", err)
+	}
+	return nil
+}
+
+// ListOrganizations returns all organizations sorted by ID
+func (d *Database) ListOrganizations() ([]*org.Organization, error) {
+	rows, err := d.query(`SELECT ` + organizationColumns + ` FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*org.Organization
+	for rows.Next() {
+		o := &org.Organization{}
+		if err := rows.Scan(&o.ID, &o.Name, &o.DailyBudgetUSD, &o.CreatedAt, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		list = append(list, o)
+	}
+	return list, rows.Err()
+}
+
+// DeleteOrganization removes an organization
+func (d *Database) DeleteOrganization(id string) error {
+	if _, err := d.exec(`DELETE FROM organizations WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/persona"
+)
+
+const personaVersionColumns = `name, version, definition_json, comment, rolled_back_from, created_by, created_at`
+
+// SavePersonaVersion inserts a new published version of a persona
+func (d *Database) SavePersonaVersion(v *persona.Version) error {
+	def, err := json.Marshal(v.Definition)
+	if err != nil {
+		return fmt.Errorf("failed to encode persona definition: %w", err)
+	}
+	var rolledBackFrom sql.NullInt64
+	if v.RolledBackFrom > 0 {
+		rolledBackFrom = sql.NullInt64{Int64: int64(v.RolledBackFrom), Valid: true}
+	}
+	_, err = d.db.Exec(`INSERT INTO persona_versions (`+personaVersionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		v.Name,
+		v.Version,
+		string(def),
+		sqlNullString(v.Comment),
+		rolledBackFrom,
+		sqlNullString(v.CreatedBy),
+		v.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save persona version: %w", err)
+	}
+	return nil
+}
+
+// GetPersonaVersion returns a persona's version, the latest for version 0,
+// or nil if it does not exist
+func (d *Database) GetPersonaVersion(name string, version int) (*persona.Version, error) {
+	var row *sql.Row
+	if version == 0 {
+		row = d.db.QueryRow(`SELECT `+personaVersionColumns+` FROM persona_versions
+			WHERE name = ? ORDER BY version DESC LIMIT 1`, name)
+	} else {
+		row = d.db.QueryRow(`SELECT `+personaVersionColumns+` FROM persona_versions
+			WHERE name = ? AND version = ?`, name, version)
+	}
+	v, err := scanPersonaVersion(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return v, err
+}
+
+// ListPersonaVersions returns a persona's versions, newest first
+func (d *Database) ListPersonaVersions(name string) ([]*persona.Version, error) {
+	rows, err := d.db.Query(`SELECT `+personaVersionColumns+` FROM persona_versions
+		WHERE name = ? ORDER BY version DESC`, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list persona versions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*persona.Version
+	for rows.Next() {
+		v, err := scanPersonaVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+// ListVersionedPersonas returns the names of personas with published
+// versions, sorted
+func (d *Database) ListVersionedPersonas() ([]string, error) {
+	rows, err := d.db.Query(`SELECT DISTINCT name FROM persona_versions ORDER BY name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list versioned personas: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan persona name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// DeletePersonaVersions removes every published version of a persona
+func (d *Database) DeletePersonaVersions(name string) error {
+	if _, err := d.db.Exec(`DELETE FROM persona_versions WHERE name = ?`, name); err != nil {
+		return fmt.Errorf("failed to delete persona versions: %w", err)
+	}
+	return nil
+}
+
+func scanPersonaVersion(row interface{ Scan(...interface{}) error }) (*persona.Version, error) {
+	v := &persona.Version{}
+	var def string
+	var comment, createdBy sql.NullString
+	var rolledBackFrom sql.NullInt64
+	if err := row.Scan(&v.Name, &v.Version, &def, &comment, &rolledBackFrom, &createdBy, &v.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan persona version: %w", err)
+	}
+	if err := json.Unmarshal([]byte(def), &v.Definition); err != nil {
+		return nil, fmt.Errorf("failed to decode persona definition: %w", err)
+	}
+	v.Comment = comment.String
+	v.RolledBackFrom = int(rolledBackFrom.Int64)
+	v.CreatedBy = createdBy.String
+	return v, nil
+}
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/policy"
+)
+
+const policyVersionColumns = `project_id, version, document_json, comment, created_by, created_at`
+
+// SavePolicyVersion inserts a new policy version of a project
+func (d *Database) SavePolicyVersion(v *policy.Version) error {
+	doc, err := json.Marshal(v.Document)
+	if err != nil {
+		return fmt.Errorf("failed to encode policy document: %w", err)
+	}
+	_, err = d.db.Exec(`INSERT INTO policy_versions (`+policyVersionColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		v.ProjectID,
+		v.Version,
+		string(doc),
+		sqlNullString(v.Comment),
+		sqlNullString(v.CreatedBy),
+		v.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save policy version: %w", err)
+	}
+	return nil
+}
+
+// GetPolicyVersion returns a project's policy version, the latest for
+// version 0, or nil if it does not exist
+func (d *Database) GetPolicyVersion(projectID string, version int) (*policy.Version, error) {
+	var row *sql.Row
+	if version == 0 {
+		row = d.db.QueryRow(`SELECT `+policyVersionColumns+` FROM policy_versions
+			WHERE project_id = ? ORDER BY version DESC LIMIT 1`, projectID)
+	} else {
+		row = d.db.QueryRow(`SELECT `+policyVersionColumns+` FROM policy_versions
+			WHERE project_id = ? AND version = ?`, projectID, version)
+	}
+	v, err := scanPolicyVersion(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return v, err
+}
+
+// ListPolicyVersions returns a project's policy versions, newest first
+func (d *Database) ListPolicyVersions(projectID string) ([]*policy.Version, error) {
+	rows, err := d.db.Query(`SELECT `+policyVersionColumns+` FROM policy_versions
+		WHERE project_id = ? ORDER BY version DESC`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list policy versions: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*policy.Version
+	for rows.Next() {
+		v, err := scanPolicyVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, rows.Err()
+}
+
+func scanPolicyVersion(row interface{ Scan(...interface{}) error }) (*policy.Version, error) {
+	v := &policy.Version{}
+	var doc string
+	var comment, createdBy sql.NullString
+	if err := row.Scan(&v.ProjectID, &v.Version, &doc, &comment, &createdBy, &v.CreatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan policy version: %w", err)
+	}
+	if err := json.Unmarshal([]byte(doc), &v.Document); err != nil {
+		return nil, fmt.Errorf("failed to decode policy document: %w", err)
+	}
+	v.Comment = comment.String
+	v.CreatedBy = createdBy.String
+	return v, nil
+}
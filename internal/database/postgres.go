@@ -1,18 +1,77 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
-// NewPostgres creates a PostgreSQL database connection.
-func NewPostgres(dsn string) (*Database, error) {
+// PoolConfig sizes the connection pool of a Postgres database. Zero values
+// take the defaults below.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// Pool defaults, sized for a few instances sharing one server
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 30 * time.Minute
+	defaultConnMaxIdleTime = 5 * time.Minute
+)
+
+func (p PoolConfig) apply(db *sql.DB) {
+	if p.MaxOpenConns <= 0 {
+		p.MaxOpenConns = defaultMaxOpenConns
+	}
+	if p.MaxIdleConns <= 0 {
+		p.MaxIdleConns = defaultMaxIdleConns
+	}
+	if p.MaxIdleConns > p.MaxOpenConns {
+		p.MaxIdleConns = p.MaxOpenConns
+	}
+	if p.ConnMaxLifetime <= 0 {
+		p.ConnMaxLifetime = defaultConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime <= 0 {
+		p.ConnMaxIdleTime = defaultConnMaxIdleTime
+	}
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
+// NewPostgres creates a PostgreSQL database connection and applies any
+// pending schema migrations. Several instances can share one database; the
+// users, activity feed, notifications and lessons live in Postgres so every
+// instance sees the same state.
+func NewPostgres(dsn string, pool PoolConfig) (*Database, error) {
+	d, err := OpenPostgres(dsn, pool)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.Migrate(context.Background()); err != nil {
+		d.Close()
+		return nil, fmt.Errorf("failed to migrate postgres: %w", err)
+	}
+	return d, nil
+}
+
+// OpenPostgres connects to a PostgreSQL database without touching its
+// schema, so migrations can be inspected and repaired
+func OpenPostgres(dsn string, pool PoolConfig) (*Database, error) {
 	db, err := sql.Open("postgres", dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open postgres: %w", err)
 	}
+	pool.apply(db)
 
 	// Test connection
 	if err := db.Ping(); err != nil {
@@ -20,124 +79,10 @@ func NewPostgres(dsn string) (*Database, error) {
 		return nil, fmt.Errorf("failed to ping postgres: %w", err)
 	}
 
-	d := &Database{
+	return &Database{
 		db:         db,
 		dbType:     "postgres",
 		supportsHA: true,
-	}
-
-	// Initialize schema
-	if err := d.initSchemaPostgres(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	// Run migrations
-	if err := d.migrateProviderOwnership(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate provider ownership: %w", err)
-	}
-
-	if err := d.migrateProviderRouting(); err != nil {
-		db.Close()
-		return nil, fmt.Errorf("failed to migrate provider routing: %w", err)
-	}
-
-	return d, nil
-}
-
-// initSchemaPostgres creates PostgreSQL-specific tables.
-func (d *Database) initSchemaPostgres() error {
-	schema := `
-	-- Global configuration key-value store
-	CREATE TABLE IF NOT EXISTS config_kv (
-		key TEXT PRIMARY KEY,
-		value TEXT NOT NULL,
-		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Distributed locks table for HA
-	CREATE TABLE IF NOT EXISTS distributed_locks (
-		lock_name TEXT PRIMARY KEY,
-		instance_id TEXT NOT NULL,
-		acquired_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		expires_at TIMESTAMP NOT NULL,
-		heartbeat_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Instance registry for tracking active instances
-	CREATE TABLE IF NOT EXISTS instances (
-		instance_id TEXT PRIMARY KEY,
-		hostname TEXT NOT NULL,
-		started_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		last_heartbeat TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		status TEXT NOT NULL DEFAULT 'active',
-		metadata JSONB
-	);
-
-	-- Global providers (shared across all projects)
-	CREATE TABLE IF NOT EXISTS providers (
-		id TEXT PRIMARY KEY,
-		name TEXT NOT NULL,
-		type TEXT NOT NULL,
-		endpoint TEXT NOT NULL,
-		model TEXT,
-		configured_model TEXT,
-		selected_model TEXT,
-		selection_reason TEXT,
-		model_score REAL,
-		selected_gpu TEXT,
-		gpu_constraints_json TEXT,
-		description TEXT,
-		requires_key BOOLEAN NOT NULL DEFAULT false,
-		key_id TEXT,
-		owner_id TEXT,
-		is_shared BOOLEAN NOT NULL DEFAULT true,
-		status TEXT NOT NULL DEFAULT 'active',
-		last_heartbeat_at TIMESTAMP,
-		last_heartbeat_latency_ms INTEGER,
-		last_heartbeat_error TEXT,
-		metrics_json TEXT,
-		schema_version TEXT NOT NULL DEFAULT '1.0',
-		attributes_json TEXT,
-		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		cost_per_mtoken REAL,
-		context_window INTEGER,
-		supports_function BOOLEAN DEFAULT false,
-		supports_vision BOOLEAN DEFAULT false,
-		supports_streaming BOOLEAN DEFAULT false,
-		tags TEXT[]
-	);
-
-	-- Request logs for analytics
-	CREATE TABLE IF NOT EXISTS request_logs (
-		id SERIAL PRIMARY KEY,
-		timestamp TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		user_id TEXT,
-		provider_id TEXT,
-		model TEXT,
-		endpoint TEXT,
-		method TEXT,
-		status_code INTEGER,
-		latency_ms INTEGER,
-		prompt_tokens INTEGER,
-		completion_tokens INTEGER,
-		total_tokens INTEGER,
-		cost_usd REAL,
-		error_message TEXT,
-		request_body_hash TEXT,
-		ip_address TEXT
-	);
-
-	-- Create indexes for performance
-	CREATE INDEX IF NOT EXISTS idx_request_logs_timestamp ON request_logs(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_request_logs_user_id ON request_logs(user_id);
-	CREATE INDEX IF NOT EXISTS idx_request_logs_provider_id ON request_logs(provider_id);
-	CREATE INDEX IF NOT EXISTS idx_distributed_locks_expires_at ON distributed_locks(expires_at);
-	CREATE INDEX IF NOT EXISTS idx_instances_last_heartbeat ON instances(last_heartbeat);
-	`
-
-	_, err := d.db.Exec(schema)
-	return err
+		dsn:        dsn,
+	}, nil
 }
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/prreview"
+)
+
+const prReviewColumns = `id, project_id, bead_id, pr_number, pr_url, branch, base, persona, provider_id, model, status, summary, findings_json, blocking, response, merged, merge_error, error, started_at, finished_at`
+
+// SavePRReview inserts or replaces an automated pull request review
+func (d *Database) SavePRReview(r *prreview.Review) error {
+	findings, err := json.Marshal(r.Findings)
+	if err != nil {
+		return fmt.Errorf("failed to encode findings: %w", err)
+	}
+	_, err = d.db.Exec(`INSERT OR REPLACE INTO pr_reviews (`+prReviewColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.ID,
+		r.ProjectID,
+		sqlNullString(r.BeadID),
+		r.PRNumber,
+		sqlNullString(r.PRURL),
+		r.Branch,
+		r.Base,
+		r.Persona,
+		sqlNullString(r.ProviderID),
+		sqlNullString(r.Model),
+		r.Status,
+		sqlNullString(r.Summary),
+		string(findings),
+		r.Blocking,
+		sqlNullString(r.Response),
+		r.Merged,
+		sqlNullString(r.MergeError),
+		sqlNullString(r.Error),
+		r.StartedAt,
+		r.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save pull request review: %w", err)
+	}
+	return nil
+}
+
+// GetPRReview returns a pull request review, or nil if it does not exist
+func (d *Database) GetPRReview(id string) (*prreview.Review, error) {
+	r, err := scanPRReview(d.db.QueryRow(`SELECT `+prReviewColumns+` FROM pr_reviews WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return r, err
+}
+
+// ListPRReviews returns a project's pull request reviews, newest first;
+// limit 0 means no limit
+func (d *Database) ListPRReviews(projectID string, limit int) ([]*prreview.Review, error) {
+	query := `SELECT ` + prReviewColumns + ` FROM pr_reviews WHERE project_id = ? ORDER BY started_at DESC`
+	args := []interface{}{projectID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pull request reviews: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*prreview.Review
+	for rows.Next() {
+		r, err := scanPRReview(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func scanPRReview(row interface{ Scan(...interface{}) error }) (*prreview.Review, error) {
+	r := &prreview.Review{}
+	var findings string
+	var beadID, prURL, providerID, model, summary, response, mergeError, errMsg sql.NullString
+	if err := row.Scan(&r.ID, &r.ProjectID, &beadID, &r.PRNumber, &prURL, &r.Branch, &r.Base, &r.Persona,
+		&providerID, &model, &r.Status, &summary, &findings, &r.Blocking, &response, &r.Merged, &mergeError,
+		&errMsg, &r.StartedAt, &r.FinishedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan pull request review: %w", err)
+	}
+	if err := json.Unmarshal([]byte(findings), &r.Findings); err != nil {
+		return nil, fmt.Errorf("failed to decode findings: %w", err)
+	}
+	r.BeadID = beadID.String
+	r.PRURL = prURL.String
+	r.ProviderID = providerID.String
+	r.Model = model.String
+	r.Summary = summary.String
+	r.Response = response.String
+	r.MergeError = mergeError.String
+	r.Error = errMsg.String
+	return r, nil
+}
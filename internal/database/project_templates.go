@@ -0,0 +1,94 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+)
+
+const projectTemplateColumns = `id, org_id, name, spec_json, created_by, created_at, updated_at`
+
+// SaveProjectTemplate inserts or replaces a project template. The columns
+// other than spec_json are kept for listing; spec_json holds the whole
+// template.
+func (d *Database) SaveProjectTemplate(t *projecttemplates.Template) error {
+	spec, err := json.Marshal(t)
+	if err != nil {
+		return fmt.Errorf("failed to encode project template: %w", err)
+	}
+	_, err = d.db.Exec(`INSERT OR REPLACE INTO project_templates (`+projectTemplateColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		t.ID,
+		sqlNullString(t.OrgID),
+		t.Name,
+		string(spec),
+		sqlNullString(t.CreatedBy),
+		t.CreatedAt,
+		t.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save project template: %w", err)
+	}
+	return nil
+}
+
+// GetProjectTemplate returns a project template, or nil if it does not exist
+func (d *Database) GetProjectTemplate(id string) (*projecttemplates.Template, error) {
+	t, err := scanProjectTemplate(d.db.QueryRow(`SELECT `+projectTemplateColumns+` FROM project_templates WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListProjectTemplates returns every project template, oldest first
+func (d *Database) ListProjectTemplates() ([]*projecttemplates.Template, error) {
+	rows, err := d.db.Query(`SELECT ` + projectTemplateColumns + ` FROM project_templates ORDER BY created_at`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list project templates: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*projecttemplates.Template
+	for rows.Next() {
+		t, err := scanProjectTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// DeleteProjectTemplate removes a project template
+func (d *Database) DeleteProjectTemplate(id string) error {
+	if _, err := d.db.Exec(`DELETE FROM project_templates WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete project template: %w", err)
+	}
+	return nil
+}
+
+func scanProjectTemplate(row interface{ Scan(...interface{}) error }) (*projecttemplates.Template, error) {
+	t := &projecttemplates.Template{}
+	var id, name, spec string
+	var orgID, createdBy sql.NullString
+	var createdAt, updatedAt time.Time
+	if err := row.Scan(&id, &orgID, &name, &spec, &createdBy, &createdAt, &updatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan project template: %w", err)
+	}
+	if err := json.Unmarshal([]byte(spec), t); err != nil {
+		return nil, fmt.Errorf("failed to decode project template: %w", err)
+	}
+	t.ID = id
+	t.OrgID = orgID.String
+	t.Name = name
+	t.CreatedBy = createdBy.String
+	t.CreatedAt = createdAt
+	t.UpdatedAt = updatedAt
+	return t, nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const proposedPatchColumns = `id, project_id, bead_id, agent_id, message, files_json, diff, fingerprint, low_risk, decision_id, status, reviewed_by, review_reason, created_at, reviewed_at`
+
+// CreateProposedPatch stores an agent commit held for approval
+func (d *Database) CreateProposedPatch(p *models.ProposedPatch) error {
+	files, err := json.Marshal(p.Files)
+	if err != nil {
+		return fmt.Errorf("failed to encode patch files: %w", err)
+	}
+	lowRisk := 0
+	if p.LowRisk {
+		lowRisk = 1
+	}
+	_, err = d.exec(`INSERT INTO proposed_patches (`+proposedPatchColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.ProjectID, p.BeadID, p.AgentID, p.Message, string(files), p.Diff, p.Fingerprint, lowRisk,
+		p.DecisionID, string(p.Status), p.ReviewedBy, p.ReviewReason, p.CreatedAt, p.ReviewedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create proposed patch: %w", err)
+	}
+	return nil
+}
+
+// GetProposedPatch returns a proposed patch, or nil if it does not exist
+func (d *Database) GetProposedPatch(id string) (*models.ProposedPatch, error) {
+	p, err := scanProposedPatch(d.queryRow(`SELECT `+proposedPatchColumns+` FROM proposed_patches WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// FindProposedPatch returns a project's most recent patch of the diff with
+// the fingerprint, or nil if none was proposed
+func (d *Database) FindProposedPatch(projectID, fingerprint string) (*models.ProposedPatch, error) {
+	p, err := scanProposedPatch(d.queryRow(`SELECT `+proposedPatchColumns+` FROM proposed_patches
+		WHERE project_id = ? AND fingerprint = ? ORDER BY created_at DESC LIMIT 1`, projectID, fingerprint))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListProposedPatches returns a project's patches in the given status, or
+// in any status when it is empty, newest first
+func (d *Database) ListProposedPatches(projectID string, status models.PatchStatus) ([]*models.ProposedPatch, error) {
+	query := `SELECT ` + proposedPatchColumns + ` FROM proposed_patches WHERE project_id = ?`
+	args := []interface{}{projectID}
+	if status != "" {
+		query += ` AND status = ?`
+		args = append(args, string(status))
+	}
+	rows, err := d.query(query+` ORDER BY created_at DESC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list proposed patches: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*models.ProposedPatch
+	for rows.Next() {
+		p, err := scanProposedPatch(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, p)
+	}
+	return list, rows.Err()
+}
+
+// ReviewProposedPatch approves or rejects a pending patch. It returns
+// false when the patch is not pending, e.g. it was reviewed already.
+func (d *Database) ReviewProposedPatch(id string, status models.PatchStatus, reviewedBy, reason string, at time.Time) (bool, error) {
+	result, err := d.exec(`UPDATE proposed_patches SET status = ?, reviewed_by = ?, review_reason = ?, reviewed_at = ? WHERE id = ? AND status = ?`,
+		string(status), reviewedBy, reason, at, id, string(models.PatchStatusPending))
+	if err != nil {
+		return false, fmt.Errorf("failed to review proposed patch: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rows > 0, nil
+}
+
+// SetProposedPatchDecision records the decision opened for a patch
+func (d *Database) SetProposedPatchDecision(id, decisionID string) error {
+	if _, err := d.exec(`UPDATE proposed_patches SET decision_id = ? WHERE id = ?`, decisionID, id); err != nil {
+		return fmt.Errorf("failed to set patch decision: %w", err)
+	}
+	return nil
+}
+
+func scanProposedPatch(row interface{ Scan(...interface{}) error }) (*models.ProposedPatch, error) {
+	p := &models.ProposedPatch{}
+	var files, status string
+	var lowRisk int
+	var reviewedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.ProjectID, &p.BeadID, &p.AgentID, &p.Message, &files, &p.Diff, &p.Fingerprint, &lowRisk,
+		&p.DecisionID, &status, &p.ReviewedBy, &p.ReviewReason, &p.CreatedAt, &reviewedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan proposed patch: %w", err)
+	}
+	if err := json.Unmarshal([]byte(files), &p.Files); err != nil {
+		return nil, fmt.Errorf("failed to decode patch files: %w", err)
+	}
+	p.LowRisk = lowRisk != 0
+	p.Status = models.PatchStatus(status)
+	if reviewedAt.Valid {
+		t := reviewedAt.Time
+		p.ReviewedAt = &t
+	}
+	return p, nil
+}
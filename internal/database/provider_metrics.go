@@ -0,0 +1,128 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+const providerMetricsColumns = `provider_id, model_params_b, heartbeat_latency_ms, avg_request_latency_ms, cost_per_mtoken, requests, failures, composite_score, recorded_at`
+
+// SaveProviderMetrics replaces the stored scorer metrics of the given
+// providers and appends them to the metrics history, in one transaction
+func (d *Database) SaveProviderMetrics(metrics []provider.ScoreMetrics) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	upsert := d.rebind(`
+		INSERT INTO provider_metrics (` + providerMetricsColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(provider_id) DO UPDATE SET
+			model_params_b = excluded.model_params_b,
+			heartbeat_latency_ms = excluded.heartbeat_latency_ms,
+			avg_request_latency_ms = excluded.avg_request_latency_ms,
+			cost_per_mtoken = excluded.cost_per_mtoken,
+			requests = excluded.requests,
+			failures = excluded.failures,
+			composite_score = excluded.composite_score,
+			recorded_at = excluded.recorded_at
+	`)
+	history := d.rebind(`INSERT INTO provider_metrics_history (` + providerMetricsColumns + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	for _, m := range metrics {
+		args := []interface{}{
+			m.ProviderID, m.ModelParamsB, m.HeartbeatLatencyMs, m.AvgRequestLatencyMs, m.CostPerMToken,
+			m.Requests, m.Failures, m.CompositeScore, m.RecordedAt,
+		}
+		if _, err := tx.Exec(upsert, args...); err != nil {
+			return fmt.Errorf("failed to save provider metrics: %w", err)
+		}
+		if _, err := tx.Exec(history, args...); err != nil {
+			return fmt.Errorf("failed to record provider metrics history: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// ListProviderMetrics returns the stored scorer metrics of every provider
+func (d *Database) ListProviderMetrics() ([]provider.ScoreMetrics, error) {
+	rows, err := d.query(`SELECT ` + providerMetricsColumns + ` FROM provider_metrics ORDER BY provider_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider metrics: %w", err)
+	}
+	defer rows.Close()
+	return scanProviderMetrics(rows)
+}
+
+// ListProviderMetricsHistory returns the metrics snapshots recorded since
+// since, oldest first. An empty providerID covers every provider; limit 0
+// means no limit, otherwise the newest limit snapshots are returned.
+func (d *Database) ListProviderMetricsHistory(providerID string, since time.Time, limit int) ([]provider.ScoreMetrics, error) {
+	query := `SELECT ` + providerMetricsColumns + ` FROM provider_metrics_history WHERE recorded_at >= ?`
+	args := []interface{}{since}
+	if providerID != "" {
+		query += ` AND provider_id = ?`
+		args = append(args, providerID)
+	}
+	query += ` ORDER BY recorded_at DESC, id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list provider metrics history: %w", err)
+	}
+	defer rows.Close()
+
+	list, err := scanProviderMetrics(rows)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+		list[i], list[j] = list[j], list[i]
+	}
+	return list, nil
+}
+
+// DeleteProviderMetricsHistory removes the snapshots recorded before
+// before and returns how many there were
+func (d *Database) DeleteProviderMetricsHistory(before time.Time) (int64, error) {
+	res, err := d.exec(`DELETE FROM provider_metrics_history WHERE recorded_at < ?`, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete provider metrics history: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// DeleteProviderMetrics removes a provider's stored scorer metrics; its
+// history ages out with the rest
+func (d *Database) DeleteProviderMetrics(providerID string) error {
+	if _, err := d.exec(`DELETE FROM provider_metrics WHERE provider_id = ?`, providerID); err != nil {
+		return fmt.Errorf("failed to delete provider metrics: %w", err)
+	}
+	return nil
+}
+
+func scanProviderMetrics(rows *sql.Rows) ([]provider.ScoreMetrics, error) {
+	var list []provider.ScoreMetrics
+	for rows.Next() {
+		var m provider.ScoreMetrics
+		if err := rows.Scan(&m.ProviderID, &m.ModelParamsB, &m.HeartbeatLatencyMs, &m.AvgRequestLatencyMs, &m.CostPerMToken,
+			&m.Requests, &m.Failures, &m.CompositeScore, &m.RecordedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan provider metrics: %w", err)
+		}
+		if m.Requests > 0 {
+			m.ErrorRate = m.Failures / m.Requests
+		}
+		list = append(list, m)
+	}
+	return list, rows.Err()
+}
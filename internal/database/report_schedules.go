@@ -0,0 +1,279 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/reports"
+)
+
+const reportScheduleColumns = `id, org_id, name, report_type, format, params_json, frequency, at, weekday,
+	day_of_month, timezone, destinations_json, enabled, next_run_at, last_run_at, last_status, created_by,
+	created_at, updated_at`
+
+const reportRunColumns = `id, schedule_id, org_id, report_type, format, trigger_type, status, period_start,
+	period_end, filename, size_bytes, destinations_json, error, started_at, finished_at`
+
+// SaveReportSchedule inserts or updates a report schedule
+func (d *Database) SaveReportSchedule(s *reports.Schedule) error {
+	var params sql.NullString
+	if len(s.Params) > 0 {
+		data, err := json.Marshal(s.Params)
+		if err != nil {
+			return fmt.Errorf("failed to encode report params: %w", err)
+		}
+		params = sql.NullString{String: string(data), Valid: true}
+	}
+	destinations, err := json.Marshal(s.Destinations)
+	if err != nil {
+		return fmt.Errorf("failed to encode report destinations: %w", err)
+	}
+
+	query := `
+		INSERT INTO report_schedules (` + reportScheduleColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			org_id = excluded.org_id,
+			name = excluded.name,
+			report_type = excluded.report_type,
+			format = excluded.format,
+			params_json = excluded.params_json,
+			frequency = excluded.frequency,
+			at = excluded.at,
+			weekday = excluded.weekday,
+			day_of_month = excluded.day_of_month,
+			timezone = excluded.timezone,
+			destinations_json = excluded.destinations_json,
+			enabled = excluded.enabled,
+			next_run_at = excluded.next_run_at,
+			last_run_at = excluded.last_run_at,
+			last_status = excluded.last_status,
+			updated_at = excluded.updated_at
+	`
+	_, err = d.db.Exec(query,
+		s.ID,
+		s.OrgID,
+		s.Name,
+		s.ReportType,
+		s.Format,
+		params,
+		s.Frequency,
+		s.At,
+		s.Weekday,
+		s.DayOfMonth,
+		s.Timezone,
+		string(destinations),
+		s.Enabled,
+		sqlNullTime(s.NextRunAt),
+		sqlNullTime(s.LastRunAt),
+		sqlNullString(s.LastStatus),
+		sqlNullString(s.CreatedBy),
+		s.CreatedAt,
+		s.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save report schedule: %w", err)
+	}
+	return nil
+}
+
+// GetReportSchedule returns a schedule, or nil if it does not exist
+func (d *Database) GetReportSchedule(id string) (*reports.Schedule, error) {
+	row := d.db.QueryRow(`SELECT `+reportScheduleColumns+` FROM report_schedules WHERE id = ?`, id)
+	s, err := scanReportSchedule(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return s, err
+}
+
+// ListReportSchedules returns an organization's schedules, or every
+// schedule when orgID is empty, oldest first
+func (d *Database) ListReportSchedules(orgID string) ([]*reports.Schedule, error) {
+	query := `SELECT ` + reportScheduleColumns + ` FROM report_schedules`
+	var args []interface{}
+	if orgID != "" {
+		query += ` WHERE org_id = ?`
+		args = append(args, orgID)
+	}
+	rows, err := d.db.Query(query+` ORDER BY created_at ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list report schedules: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*reports.Schedule
+	for rows.Next() {
+		s, err := scanReportSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, s)
+	}
+	return list, rows.Err()
+}
+
+// DeleteReportSchedule removes a schedule and its run log
+func (d *Database) DeleteReportSchedule(id string) error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM report_runs WHERE schedule_id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete report runs: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM report_schedules WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete report schedule: %w", err)
+	}
+	return tx.Commit()
+}
+
+func scanReportSchedule(row interface{ Scan(...interface{}) error }) (*reports.Schedule, error) {
+	s := &reports.Schedule{}
+	var destinations string
+	var params, lastStatus, createdBy sql.NullString
+	var nextRun, lastRun sql.NullTime
+	if err := row.Scan(&s.ID, &s.OrgID, &s.Name, &s.ReportType, &s.Format, &params, &s.Frequency, &s.At,
+		&s.Weekday, &s.DayOfMonth, &s.Timezone, &destinations, &s.Enabled, &nextRun, &lastRun, &lastStatus,
+		&createdBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan report schedule: %w", err)
+	}
+	if params.Valid && params.String != "" {
+		if err := json.Unmarshal([]byte(params.String), &s.Params); err != nil {
+			return nil, fmt.Errorf("failed to decode report params: %w", err)
+		}
+	}
+	if err := json.Unmarshal([]byte(destinations), &s.Destinations); err != nil {
+		return nil, fmt.Errorf("failed to decode report destinations: %w", err)
+	}
+	s.NextRunAt = nullTimePtr(nextRun)
+	s.LastRunAt = nullTimePtr(lastRun)
+	s.LastStatus = lastStatus.String
+	s.CreatedBy = createdBy.String
+	return s, nil
+}
+
+// SaveReportRun inserts or updates a report run
+func (d *Database) SaveReportRun(r *reports.Run) error {
+	var destinations sql.NullString
+	if len(r.Destinations) > 0 {
+		data, err := json.Marshal(r.Destinations)
+		if err != nil {
+			return fmt.Errorf("failed to encode run destinations: %w", err)
+		}
+		destinations = sql.NullString{String: string(data), Valid: true}
+	}
+
+	query := `
+		INSERT INTO report_runs (` + reportRunColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status = excluded.status,
+			filename = excluded.filename,
+			size_bytes = excluded.size_bytes,
+			destinations_json = excluded.destinations_json,
+			error = excluded.error,
+			finished_at = excluded.finished_at
+	`
+	_, err := d.db.Exec(query,
+		r.ID,
+		r.ScheduleID,
+		r.OrgID,
+		r.ReportType,
+		r.Format,
+		r.Trigger,
+		r.Status,
+		r.PeriodStart,
+		r.PeriodEnd,
+		sqlNullString(r.Filename),
+		r.SizeBytes,
+		destinations,
+		sqlNullString(r.Error),
+		r.StartedAt,
+		r.FinishedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save report run: %w", err)
+	}
+	return nil
+}
+
+// ListReportRuns returns a page of the run log and the number of runs
+// matching f
+func (d *Database) ListReportRuns(f reports.RunFilter) ([]*reports.Run, int, error) {
+	var where []string
+	var args []interface{}
+	if f.ScheduleID != "" {
+		where = append(where, "schedule_id = ?")
+		args = append(args, f.ScheduleID)
+	}
+	if f.Status != "" {
+		where = append(where, "status = ?")
+		args = append(args, f.Status)
+	}
+	clause := ""
+	if len(where) > 0 {
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var total int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM report_runs`+clause, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count report runs: %w", err)
+	}
+
+	cols := []string{"started_at", "id"}
+	if f.After != nil {
+		after, afterArgs, err := keysetAfter(cols, f.After, !f.Ascending)
+		if err != nil {
+			return nil, 0, err
+		}
+		where = append(where, after)
+		args = append(args, afterArgs...)
+		clause = " WHERE " + strings.Join(where, " AND ")
+	}
+	query := `SELECT ` + reportRunColumns + ` FROM report_runs` + clause + orderBy(cols, !f.Ascending)
+	if f.Limit > 0 {
+		query += ` LIMIT ? OFFSET ?`
+		args = append(args, f.Limit, f.Offset)
+	}
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list report runs: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*reports.Run
+	for rows.Next() {
+		r, err := scanReportRun(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		list = append(list, r)
+	}
+	return list, total, rows.Err()
+}
+
+func scanReportRun(row interface{ Scan(...interface{}) error }) (*reports.Run, error) {
+	r := &reports.Run{}
+	var filename, destinations, errMsg sql.NullString
+	if err := row.Scan(&r.ID, &r.ScheduleID, &r.OrgID, &r.ReportType, &r.Format, &r.Trigger, &r.Status,
+		&r.PeriodStart, &r.PeriodEnd, &filename, &r.SizeBytes, &destinations, &errMsg, &r.StartedAt,
+		&r.FinishedAt); err != nil {
+		return nil, fmt.Errorf("failed to scan report run: %w", err)
+	}
+	if destinations.Valid && destinations.String != "" {
+		if err := json.Unmarshal([]byte(destinations.String), &r.Destinations); err != nil {
+			return nil, fmt.Errorf("failed to decode run destinations: %w", err)
+		}
+	}
+	r.Filename = filename.String
+	r.Error = errMsg.String
+	return r, nil
+}
@@ -0,0 +1,179 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/scheduler"
+)
+
+const schedulerTimerColumns = `id, activity, input_json, interval_ms, fire_at, runs, attempts, lease_owner, lease_until,
+	last_run_at, last_error, created_at, updated_at`
+
+// SaveSchedulerTimer inserts or replaces a timer of the embedded scheduler
+func (d *Database) SaveSchedulerTimer(t *scheduler.Timer) error {
+	_, err := d.exec(`
+		INSERT INTO scheduler_timers (`+schedulerTimerColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			activity = excluded.activity,
+			input_json = excluded.input_json,
+			interval_ms = excluded.interval_ms,
+			fire_at = excluded.fire_at,
+			runs = excluded.runs,
+			attempts = excluded.attempts,
+			lease_owner = excluded.lease_owner,
+			lease_until = excluded.lease_until,
+			last_run_at = excluded.last_run_at,
+			last_error = excluded.last_error,
+			updated_at = excluded.updated_at
+	`,
+		t.ID,
+		t.Activity,
+		sqlNullString(string(t.Input)),
+		t.Interval.Milliseconds(),
+		t.FireAt.UTC(),
+		t.Runs,
+		t.Attempts,
+		sqlNullString(t.LeaseOwner),
+		sqlNullTime(utcPtr(t.LeaseUntil)),
+		sqlNullTime(utcPtr(t.LastRunAt)),
+		sqlNullString(t.LastError),
+		t.CreatedAt.UTC(),
+		t.UpdatedAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save scheduler timer: %w", err)
+	}
+	return nil
+}
+
+// GetSchedulerTimer returns a timer, or nil if it does not exist
+func (d *Database) GetSchedulerTimer(id string) (*scheduler.Timer, error) {
+	t, err := scanSchedulerTimer(d.queryRow(`SELECT `+schedulerTimerColumns+` FROM scheduler_timers WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return t, err
+}
+
+// ListSchedulerTimers returns every timer, soonest first
+func (d *Database) ListSchedulerTimers() ([]*scheduler.Timer, error) {
+	rows, err := d.query(`SELECT ` + schedulerTimerColumns + ` FROM scheduler_timers ORDER BY fire_at ASC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scheduler timers: %w", err)
+	}
+	defer rows.Close()
+
+	var list []*scheduler.Timer
+	for rows.Next() {
+		t, err := scanSchedulerTimer(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, t)
+	}
+	return list, rows.Err()
+}
+
+// DeleteSchedulerTimer removes a timer
+func (d *Database) DeleteSchedulerTimer(id string) error {
+	if _, err := d.exec(`DELETE FROM scheduler_timers WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("failed to delete scheduler timer: %w", err)
+	}
+	return nil
+}
+
+// ClaimSchedulerTimer leases a due timer to owner until until, unless a
+// lease that has not expired holds it. The single conditional UPDATE keeps
+// two instances from claiming the same firing.
+func (d *Database) ClaimSchedulerTimer(id, owner string, now, until time.Time) (bool, error) {
+	now = now.UTC()
+	res, err := d.exec(`
+		UPDATE scheduler_timers SET lease_owner = ?, lease_until = ?
+		WHERE id = ? AND fire_at <= ? AND (lease_until IS NULL OR lease_until <= ?)
+	`, owner, until.UTC(), id, now, now)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim scheduler timer: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// FinishSchedulerTimer saves t's next firing and releases its lease, if
+// owner still holds it
+func (d *Database) FinishSchedulerTimer(t *scheduler.Timer, owner string) (bool, error) {
+	res, err := d.exec(`
+		UPDATE scheduler_timers SET fire_at = ?, runs = ?, attempts = ?, lease_owner = ?, lease_until = ?,
+			last_run_at = ?, last_error = ?, updated_at = ?
+		WHERE id = ? AND lease_owner = ?
+	`,
+		t.FireAt.UTC(),
+		t.Runs,
+		t.Attempts,
+		sqlNullString(t.LeaseOwner),
+		sqlNullTime(utcPtr(t.LeaseUntil)),
+		sqlNullTime(utcPtr(t.LastRunAt)),
+		sqlNullString(t.LastError),
+		t.UpdatedAt.UTC(),
+		t.ID,
+		owner,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to finish scheduler timer: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+func scanSchedulerTimer(row interface{ Scan(...interface{}) error }) (*scheduler.Timer, error) {
+	t := &scheduler.Timer{}
+	var input, leaseOwner, lastError sql.NullString
+	var leaseUntil, lastRunAt sql.NullTime
+	var intervalMs int64
+	err := row.Scan(
+		&t.ID,
+		&t.Activity,
+		&input,
+		&intervalMs,
+		&t.FireAt,
+		&t.Runs,
+		&t.Attempts,
+		&leaseOwner,
+		&leaseUntil,
+		&lastRunAt,
+		&lastError,
+		&t.CreatedAt,
+		&t.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan scheduler timer: %w", err)
+	}
+	if input.Valid {
+		t.Input = []byte(input.String)
+	}
+	t.Interval = time.Duration(intervalMs) * time.Millisecond
+	t.LeaseOwner = leaseOwner.String
+	t.LeaseUntil = nullTimePtr(leaseUntil)
+	t.LastRunAt = nullTimePtr(lastRunAt)
+	t.LastError = lastError.String
+	return t, nil
+}
+
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	v := t.UTC()
+	return &v
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/search"
+)
+
+// searchCandidates caps how many of the newest matches SQLite ranks for a
+// search; the best of them are returned
+const searchCandidates = 1000
+
+// searchTitleWeight is how much more a match in a title counts than one in
+// a body
+const searchTitleWeight = 2.0
+
+// SaveSearchDocument inserts or replaces a document of the search index
+func (d *Database) SaveSearchDocument(doc *search.Document) error {
+	query := `
+		INSERT INTO search_documents (kind, doc_id, org_id, project_id, actor_id, title, body, occurred_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(kind, doc_id) DO UPDATE SET
+			org_id = excluded.org_id,
+			project_id = excluded.project_id,
+			actor_id = excluded.actor_id,
+			title = excluded.title,
+			body = excluded.body,
+			occurred_at = excluded.occurred_at
+	`
+	_, err := d.exec(query,
+		doc.Kind,
+		doc.ID,
+		org.Normalize(doc.OrgID),
+		sqlNullString(doc.ProjectID),
+		sqlNullString(doc.ActorID),
+		doc.Title,
+		doc.Body,
+		doc.OccurredAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save search document: %w", err)
+	}
+	return nil
+}
+
+// DeleteSearchDocument removes a document from the search index
+func (d *Database) DeleteSearchDocument(kind, id string) error {
+	if _, err := d.exec(`DELETE FROM search_documents WHERE kind = ? AND doc_id = ?`, kind, id); err != nil {
+		return fmt.Errorf("failed to delete search document: %w", err)
+	}
+	return nil
+}
+
+// SearchDocuments returns the documents matching every term of q, best
+// first. Postgres ranks with ts_rank over weighted tsvectors. SQLite ranks
+// the newest searchCandidates matches by how much of each term's use
+// across the index falls in the document, counting titles double.
+func (d *Database) SearchDocuments(q search.Query) ([]*search.Result, error) {
+	if len(q.Terms) == 0 {
+		return nil, nil
+	}
+	where, args := searchWhere(q)
+
+	var query string
+	if d.dbType == "postgres" {
+		terms := make([]string, len(q.Terms))
+		for i, t := range q.Terms {
+			terms[i] = t + ":*"
+		}
+		query = `
+			SELECT d.kind, d.doc_id, d.project_id, d.actor_id, d.title, d.occurred_at,
+				ts_headline('simple', d.title || ' ' || d.body, q, 'StartSel=[, StopSel=], MaxWords=12, MinWords=4'),
+				ts_rank(d.search_vector, q)
+			FROM search_documents d, to_tsquery('simple', ?) q
+			WHERE d.search_vector @@ q` + where + `
+			ORDER BY 8 DESC, d.occurred_at DESC
+			LIMIT ?`
+		args = append([]interface{}{strings.Join(terms, " & ")}, args...)
+		args = append(args, q.Limit)
+	} else {
+		terms := make([]string, len(q.Terms))
+		for i, t := range q.Terms {
+			terms[i] = t + "*"
+		}
+		query = `
+			SELECT d.kind, d.doc_id, d.project_id, d.actor_id, d.title, d.occurred_at,
+				snippet(search_fts, '[', ']', '...', -1, 12), matchinfo(search_fts, 'pcx')
+			FROM search_fts JOIN search_documents d ON d.id = search_fts.docid
+			WHERE search_fts MATCH ?` + where + `
+			ORDER BY d.occurred_at DESC
+			LIMIT ?`
+		args = append([]interface{}{strings.Join(terms, " ")}, args...)
+		args = append(args, searchCandidates)
+	}
+
+	rows, err := d.query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*search.Result
+	for rows.Next() {
+		r := &search.Result{}
+		var projectID, actorID sql.NullString
+		var rank interface{}
+		if err := rows.Scan(&r.Kind, &r.ID, &projectID, &actorID, &r.Title, &r.OccurredAt, &r.Snippet, &rank); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		r.ProjectID = projectID.String
+		r.ActorID = actorID.String
+		switch rank := rank.(type) {
+		case []byte:
+			r.Score = ftsScore(rank)
+		case float64:
+			r.Score = rank
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if d.dbType != "postgres" {
+		// Candidates arrive newest first, which breaks ties
+		sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+		if len(results) > q.Limit {
+			results = results[:q.Limit]
+		}
+	}
+	return results, nil
+}
+
+// searchWhere builds the filter conditions of q, to follow the match
+func searchWhere(q search.Query) (string, []interface{}) {
+	query := ""
+	var args []interface{}
+	if q.Kind != "" {
+		query += " AND d.kind = ?"
+		args = append(args, q.Kind)
+	}
+	if q.OrgID != "" {
+		query += " AND d.org_id = ?"
+		args = append(args, q.OrgID)
+	}
+	if q.ProjectID != "" {
+		query += " AND d.project_id = ?"
+		args = append(args, q.ProjectID)
+	}
+	if q.ActorID != "" {
+		query += " AND d.actor_id = ?"
+		args = append(args, q.ActorID)
+	}
+	if !q.Since.IsZero() {
+		query += " AND d.occurred_at >= ?"
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		query += " AND d.occurred_at <= ?"
+		args = append(args, q.Until)
+	}
+	return query, args
+}
+
+// ftsScore ranks an FTS4 match from matchinfo(..., 'pcx'): for every term
+// and column, the share of the term's hits across the index that fall in
+// this document, with titles (column 0) weighted by searchTitleWeight
+func ftsScore(info []byte) float64 {
+	v := make([]uint32, len(info)/4)
+	for i := range v {
+		v[i] = binary.NativeEndian.Uint32(info[i*4:])
+	}
+	if len(v) < 2 {
+		return 0
+	}
+	phrases, cols := int(v[0]), int(v[1])
+	score := 0.0
+	for p := 0; p < phrases; p++ {
+		for c := 0; c < cols; c++ {
+			x := 2 + 3*(p*cols+c)
+			if x+1 >= len(v) || v[x] == 0 || v[x+1] == 0 {
+				continue
+			}
+			weight := 1.0
+			if c == 0 {
+				weight = searchTitleWeight
+			}
+			score += weight * float64(v[x]) / float64(v[x+1])
+		}
+	}
+	return score
+}
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// sharedLessonColumns are the columns scanned by scanSharedLessons
+const sharedLessonColumns = `id, project_id, category, title, detail, source_bead_id, source_agent_id, relevance_score, created_at, embedding_version,
+	org_id, share_status, promoted_from`
+
+// ListSharedLessons returns up to limit of an organization's shared
+// lessons in the given status, or in any status when it is empty, newest
+// first
+func (d *Database) ListSharedLessons(orgID string, status models.ShareStatus, limit int) ([]*models.Lesson, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	where := "project_id = '' AND org_id = ? AND share_status <> ''"
+	args := []interface{}{orgID}
+	if status != "" {
+		where = "project_id = '' AND org_id = ? AND share_status = ?"
+		args = append(args, string(status))
+	}
+	rows, err := d.query(`
+		SELECT `+sharedLessonColumns+`
+		FROM lessons
+		WHERE `+where+`
+		ORDER BY created_at DESC
+		LIMIT ?`,
+		append(args, limit)...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shared lessons: %w", err)
+	}
+	defer rows.Close()
+	return scanSharedLessons(rows)
+}
+
+// SearchSharedLessonsByEmbeddings is SearchLessonsByEmbeddings over an
+// organization's approved shared lessons
+func (d *Database) SearchSharedLessonsByEmbeddings(orgID string, queries map[string][]float32, topK int) ([]*models.Lesson, error) {
+	return d.searchLessons("project_id = '' AND org_id = ? AND share_status = ?",
+		[]interface{}{orgID, string(models.ShareStatusShared)}, queries, topK)
+}
+
+// SharedLessonPromotedFrom returns the organization's shared lesson
+// promoted from a project lesson, or nil when it has not been promoted
+func (d *Database) SharedLessonPromotedFrom(orgID, lessonID string) (*models.Lesson, error) {
+	rows, err := d.query(`
+		SELECT `+sharedLessonColumns+`
+		FROM lessons
+		WHERE project_id = '' AND org_id = ? AND promoted_from = ?
+		LIMIT 1`,
+		orgID, lessonID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find promoted lesson: %w", err)
+	}
+	defer rows.Close()
+	lessons, err := scanSharedLessons(rows)
+	if err != nil || len(lessons) == 0 {
+		return nil, err
+	}
+	return lessons[0], nil
+}
+
+// ApproveSharedLesson moves a proposed shared lesson of an organization
+// into the prompts of its projects
+func (d *Database) ApproveSharedLesson(orgID, id string) error {
+	result, err := d.exec(`UPDATE lessons SET share_status = ? WHERE id = ? AND project_id = '' AND org_id = ? AND share_status = ?`,
+		string(models.ShareStatusShared), id, orgID, string(models.ShareStatusProposed))
+	if err != nil {
+		return fmt.Errorf("failed to approve shared lesson: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("proposed shared lesson not found: %s", id)
+	}
+	return nil
+}
+
+// DeleteSharedLesson removes a shared lesson of an organization, whether
+// rejecting a proposal or retiring an approved lesson. The project lesson
+// it was promoted from is left in place.
+func (d *Database) DeleteSharedLesson(orgID, id string) error {
+	result, err := d.exec(`DELETE FROM lessons WHERE id = ? AND project_id = '' AND org_id = ? AND share_status <> ''`, id, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete shared lesson: %w", err)
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rows == 0 {
+		return fmt.Errorf("shared lesson not found: %s", id)
+	}
+	return nil
+}
+
+// scanSharedLessons scans rows of sharedLessonColumns
+func scanSharedLessons(rows *sql.Rows) ([]*models.Lesson, error) {
+	var lessons []*models.Lesson
+	for rows.Next() {
+		l := &models.Lesson{}
+		var shareStatus string
+		if err := rows.Scan(&l.ID, &l.ProjectID, &l.Category, &l.Title, &l.Detail,
+			&l.SourceBeadID, &l.SourceAgentID, &l.RelevanceScore, &l.CreatedAt, &l.EmbeddingVersion,
+			&l.OrgID, &shareStatus, &l.PromotedFrom); err != nil {
+			return nil, fmt.Errorf("failed to scan shared lesson: %w", err)
+		}
+		l.ShareStatus = models.ShareStatus(shareStatus)
+		lessons = append(lessons, l)
+	}
+	return lessons, rows.Err()
+}
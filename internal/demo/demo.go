@@ -0,0 +1,188 @@
+// Package demo provisions demo projects: a synthetic git repository with
+// seeded bugs filed as beads, worked by a scripted mock provider. New users
+// can watch the full dispatch, commit, pull request and review cycle without
+// connecting a real repository or model. The remote is a local bare
+// repository and pull requests are kept here rather than on GitHub.
+package demo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// ProviderID is the ID of the scripted mock provider that works demo beads
+const ProviderID = "demo-mock"
+
+// Project is a provisioned demo project
+type Project struct {
+	ProjectID string    `json:"project_id"`
+	WorkDir   string    `json:"work_dir"`
+	Origin    string    `json:"origin"`
+	BeadIDs   []string  `json:"bead_ids"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// step is one scripted agent turn
+type step struct {
+	notes  string
+	action actions.Action
+}
+
+// Manager provisions demo projects, scripts the mock provider's replies and
+// hosts their pull requests. Demo projects live for the life of the process.
+type Manager struct {
+	beads actions.BeadCreator
+
+	mu       sync.Mutex
+	projects map[string]*Project       // by project ID
+	scripts  map[string][]step         // by bead ID
+	pulls    map[string][]*PullRequest // by project ID
+}
+
+// NewManager creates a demo manager that files beads through beads
+func NewManager(beads actions.BeadCreator) *Manager {
+	return &Manager{
+		beads:    beads,
+		projects: make(map[string]*Project),
+		scripts:  make(map[string][]step),
+		pulls:    make(map[string][]*PullRequest),
+	}
+}
+
+// Provision writes the synthetic repository to workDir with a bare remote at
+// origin, files a bead per seeded bug in the project and scripts the coder
+// for each. Every bead gets an agent branch off main to work on.
+func (m *Manager) Provision(ctx context.Context, projectID, workDir, origin string) (*Project, error) {
+	if projectID == "" {
+		return nil, fmt.Errorf("project ID is required")
+	}
+	if err := initRepo(ctx, workDir, origin); err != nil {
+		return nil, fmt.Errorf("failed to create demo repository: %w", err)
+	}
+
+	dp := &Project{ProjectID: projectID, WorkDir: workDir, Origin: origin, CreatedAt: time.Now().UTC()}
+	scripts := make(map[string][]step)
+	for i, bug := range seededBugs {
+		bead, err := m.beads.CreateBead(bug.Title, bug.Description, models.BeadPriority(i+1), "bug", projectID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to file demo bead: %w", err)
+		}
+		branch := "agent/" + bead.ID
+		if _, err := git(ctx, workDir, "branch", branch, "main"); err != nil {
+			return nil, err
+		}
+		dp.BeadIDs = append(dp.BeadIDs, bead.ID)
+		scripts[bead.ID] = coderScript(bead.ID, branch, bug)
+	}
+
+	m.mu.Lock()
+	m.projects[projectID] = dp
+	for id, script := range scripts {
+		m.scripts[id] = script
+	}
+	m.mu.Unlock()
+
+	copied := *dp
+	return &copied, nil
+}
+
+// List returns the provisioned demo projects, oldest first
+func (m *Manager) List() []Project {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Project, 0, len(m.projects))
+	for _, dp := range m.projects {
+		list = append(list, *dp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// coderScript checks out the bead's branch, fixes the bug with a regression
+// test, commits, pushes (which runs the tests) and opens a pull request
+func coderScript(beadID, branch string, bug Bug) []step {
+	return []step{
+		{"Switching to this bead's branch.", actions.Action{Type: actions.ActionGitCheckout, Branch: branch}},
+		{"Reading the code the bug report points at.", actions.Action{Type: actions.ActionReadFile, Path: bug.Path}},
+		{"Found it. Applying the fix.", actions.Action{Type: actions.ActionWriteFile, Path: bug.Path, Content: bug.Fixed}},
+		{"Adding a regression test.", actions.Action{Type: actions.ActionWriteFile, Path: bug.TestPath, Content: bug.FixedTest}},
+		{"Committing the fix.", actions.Action{Type: actions.ActionGitCommit, CommitMessage: bug.Commit}},
+		{"Pushing; the pre-push gate builds and runs the tests.", actions.Action{Type: actions.ActionGitPush, Branch: branch, SetUpstream: true}},
+		{"Opening a pull request for review.", actions.Action{
+			Type:    actions.ActionCreatePR,
+			Branch:  branch,
+			PRBase:  "main",
+			PRTitle: bug.Commit,
+			PRBody:  fmt.Sprintf("Fixes %s: %s\n\n%s", beadID, bug.Title, bug.Description),
+		}},
+		{"Fix is up for review.", actions.Action{Type: actions.ActionCloseBead, BeadID: beadID, Reason: "Fixed with a regression test; pull request opened"}},
+	}
+}
+
+// reviewScript reads a pull request's diff and approves it
+func reviewScript(beadID string, number int) []step {
+	return []step{
+		{"Fetching the pull request and its diff.", actions.Action{Type: actions.ActionFetchPR, PRNumber: number, IncludeDiff: true}},
+		{"The change is small, fixes the bug and adds a test.", actions.Action{
+			Type:        actions.ActionSubmitReview,
+			PRNumber:    number,
+			ReviewEvent: "APPROVE",
+			CommentBody: "Fix is minimal and covered by a new regression test. LGTM.",
+		}},
+		{"Review submitted.", actions.Action{Type: actions.ActionCloseBead, BeadID: beadID, Reason: fmt.Sprintf("Approved PR #%d", number)}},
+	}
+}
+
+// beadPattern finds the bead a dispatch is working in its task prompt
+var beadPattern = regexp.MustCompile(`Work on bead (\S+):`)
+
+// Respond answers a chat completion for a scripted bead with the next step
+// of its script. The number of replies already in the conversation says how
+// far along it is; past the end the last step repeats. Requests for other
+// beads are left to the mock's echo.
+func (m *Manager) Respond(req *provider.ChatCompletionRequest) (string, bool) {
+	beadID, turn := "", 0
+	for _, msg := range req.Messages {
+		switch msg.Role {
+		case "assistant":
+			turn++
+		case "user":
+			if beadID == "" {
+				if match := beadPattern.FindStringSubmatch(msg.Content); match != nil {
+					beadID = match[1]
+				}
+			}
+		}
+	}
+	if beadID == "" {
+		return "", false
+	}
+
+	m.mu.Lock()
+	script := m.scripts[beadID]
+	m.mu.Unlock()
+	if len(script) == 0 {
+		return "", false
+	}
+	if turn >= len(script) {
+		turn = len(script) - 1
+	}
+
+	reply, err := json.Marshal(actions.ActionEnvelope{
+		Actions: []actions.Action{script[turn].action},
+		Notes:   script[turn].notes,
+	})
+	if err != nil {
+		return "", false
+	}
+	return string(reply), true
+}
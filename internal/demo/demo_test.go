@@ -0,0 +1,239 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// fakeBeads files beads in memory
+type fakeBeads struct {
+	beads []*models.Bead
+}
+
+func (f *fakeBeads) CreateBead(title, description string, priority models.BeadPriority, beadType, projectID string) (*models.Bead, error) {
+	b := &models.Bead{
+		ID:          fmt.Sprintf("dp-%d", len(f.beads)+1),
+		Title:       title,
+		Description: description,
+		Priority:    priority,
+		Type:        beadType,
+		ProjectID:   projectID,
+	}
+	f.beads = append(f.beads, b)
+	return b, nil
+}
+
+func provision(t *testing.T) (*Manager, *fakeBeads, *Project) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	beads := &fakeBeads{}
+	m := NewManager(beads)
+	dp, err := m.Provision(context.Background(), "proj-demo", filepath.Join(dir, "proj-demo"), filepath.Join(dir, "proj-demo.git"))
+	if err != nil {
+		t.Fatalf("Provision: %v", err)
+	}
+	return m, beads, dp
+}
+
+// goTest runs the synthetic repository's tests
+func goTest(t *testing.T, dir string) error {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go not available")
+	}
+	cmd := exec.Command("go", "test", "./...")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GOWORK=off", "GOFLAGS=", "GOTOOLCHAIN=local")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v\n%s", err, out)
+	}
+	return nil
+}
+
+func TestProvision(t *testing.T) {
+	m, beads, dp := provision(t)
+
+	if len(dp.BeadIDs) != len(seededBugs) || len(beads.beads) != len(seededBugs) {
+		t.Fatalf("expected a bead per seeded bug, got %v", dp.BeadIDs)
+	}
+	for i, b := range beads.beads {
+		if b.Type != "bug" || b.ProjectID != "proj-demo" || b.Title != seededBugs[i].Title {
+			t.Errorf("bead %d = %+v", i, b)
+		}
+		if _, err := git(context.Background(), dp.WorkDir, "rev-parse", "--verify", "agent/"+b.ID); err != nil {
+			t.Errorf("expected an agent branch for %s: %v", b.ID, err)
+		}
+	}
+	if head, err := git(context.Background(), dp.Origin, "rev-parse", "main"); err != nil || head == "" {
+		t.Errorf("expected main pushed to origin: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dp.WorkDir, ".beads", "beads")); err != nil {
+		t.Errorf("expected a beads directory: %v", err)
+	}
+	if !m.HandlesProject("proj-demo") || m.HandlesProject("proj-real") {
+		t.Error("expected only the demo project to be handled")
+	}
+	if list := m.List(); len(list) != 1 || list[0].ProjectID != "proj-demo" {
+		t.Errorf("List = %+v", list)
+	}
+
+	if _, err := m.Provision(context.Background(), "proj-demo", dp.WorkDir, dp.Origin); err == nil {
+		t.Error("expected provisioning over an existing demo to fail")
+	}
+}
+
+func TestSeededRepoTests(t *testing.T) {
+	_, _, dp := provision(t)
+	if err := goTest(t, dp.WorkDir); err != nil {
+		t.Fatalf("seeded repository tests should pass: %v", err)
+	}
+	for _, bug := range seededBugs {
+		for path, content := range map[string]string{bug.Path: bug.Fixed, bug.TestPath: bug.FixedTest} {
+			if err := os.WriteFile(filepath.Join(dp.WorkDir, path), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := goTest(t, dp.WorkDir); err != nil {
+		t.Fatalf("fixed repository tests should pass: %v", err)
+	}
+}
+
+func chat(beadID string, turns int) *provider.ChatCompletionRequest {
+	req := &provider.ChatCompletionRequest{Messages: []provider.ChatMessage{
+		{Role: "system", Content: "You are an agent."},
+		{Role: "user", Content: fmt.Sprintf("Work on bead %s: a bug\n\nDetails", beadID)},
+	}}
+	for i := 0; i < turns; i++ {
+		req.Messages = append(req.Messages,
+			provider.ChatMessage{Role: "assistant", Content: "{}"},
+			provider.ChatMessage{Role: "user", Content: "result"})
+	}
+	return req
+}
+
+func TestRespond_FollowsScript(t *testing.T) {
+	m, _, dp := provision(t)
+	beadID := dp.BeadIDs[0]
+
+	var types []string
+	for turn := 0; turn < len(coderScript(beadID, "", seededBugs[0]))+1; turn++ {
+		reply, ok := m.Respond(chat(beadID, turn))
+		if !ok {
+			t.Fatalf("turn %d: expected a scripted reply", turn)
+		}
+		// Replies must parse in both the legacy and the simple JSON modes
+		env, err := actions.DecodeStrict([]byte(reply))
+		if err != nil {
+			t.Fatalf("turn %d: %v\n%s", turn, err, reply)
+		}
+		if _, err := actions.ParseSimpleJSON([]byte(reply)); err != nil {
+			t.Fatalf("turn %d: simple JSON: %v", turn, err)
+		}
+		types = append(types, env.Actions[0].Type)
+	}
+	want := "git_checkout read_file write_file write_file git_commit git_push create_pr close_bead close_bead"
+	if got := strings.Join(types, " "); got != want {
+		t.Errorf("script = %s, want %s", got, want)
+	}
+
+	if _, ok := m.Respond(chat("bd-unknown", 0)); ok {
+		t.Error("expected no reply for an unscripted bead")
+	}
+	if _, ok := m.Respond(&provider.ChatCompletionRequest{Messages: []provider.ChatMessage{{Role: "user", Content: "hello"}}}); ok {
+		t.Error("expected no reply without a bead")
+	}
+}
+
+func TestPullRequestCycle(t *testing.T) {
+	m, beads, dp := provision(t)
+	ctx := context.Background()
+	beadID, bug := dp.BeadIDs[0], seededBugs[0]
+	branch := "agent/" + beadID
+
+	if _, err := m.CreatePR(ctx, "proj-demo", beadID, "fix", "", "main", branch); err == nil {
+		t.Fatal("expected a pull request without changes to be refused")
+	}
+
+	// Do what the scripted coder does
+	if _, err := git(ctx, dp.WorkDir, "checkout", branch); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dp.WorkDir, bug.Path), []byte(bug.Fixed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{{"commit", "-am", bug.Commit}, {"push", "-u", "origin", branch}} {
+		if _, err := git(ctx, dp.WorkDir, args...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	created, err := m.CreatePR(ctx, "proj-demo", beadID, bug.Commit, "body", "main", "")
+	if err != nil {
+		t.Fatalf("CreatePR: %v", err)
+	}
+	if created["pr_number"] != 1 || created["branch"] != branch || created["pr_url"] != "demo://proj-demo/pull/1" {
+		t.Errorf("CreatePR = %v", created)
+	}
+	review := beads.beads[len(beads.beads)-1]
+	if review.Type != "pr-review" || review.ID != created["review_bead_id"] || !strings.HasPrefix(review.Title, "Code review: PR #1") {
+		t.Errorf("review bead = %+v", review)
+	}
+
+	// The review bead is scripted to fetch the diff and approve
+	reply, ok := m.Respond(chat(review.ID, 0))
+	if !ok || !strings.Contains(reply, `"fetch_pr"`) {
+		t.Errorf("review turn 0 = %s", reply)
+	}
+	fetched, err := m.FetchPR(ctx, "proj-demo", 1, true)
+	if err != nil {
+		t.Fatalf("FetchPR: %v", err)
+	}
+	if diff, _ := fetched["diff"].(string); !strings.Contains(diff, "+\tr := []rune(s)") {
+		t.Errorf("diff = %q", diff)
+	}
+	if err := m.MergePR(ctx, "proj-demo", 1); err == nil {
+		t.Error("expected an unapproved pull request not to merge")
+	}
+	if err := m.AddComment(ctx, "proj-demo", 1, bug.Path, 3, "handles runes", "agent-reviewer"); err != nil {
+		t.Fatalf("AddComment: %v", err)
+	}
+	if _, err := m.SubmitReview(ctx, "proj-demo", 1, "APPROVE", "LGTM", "agent-reviewer"); err != nil {
+		t.Fatalf("SubmitReview: %v", err)
+	}
+
+	pulls, err := m.PullRequests("proj-demo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pulls) != 1 || pulls[0].State != PRStateApproved || len(pulls[0].Reviews) != 1 || pulls[0].Reviews[0].ReviewerID != "agent-reviewer" {
+		t.Errorf("pulls = %+v", pulls)
+	}
+	if len(pulls[0].Comments) != 1 || pulls[0].Comments[0].Line != 3 {
+		t.Errorf("comments = %+v", pulls[0].Comments)
+	}
+	if err := m.MergePR(ctx, "proj-demo", 1); err != nil {
+		t.Fatalf("MergePR: %v", err)
+	}
+	if _, err := m.SubmitReview(ctx, "proj-demo", 1, "REQUEST_CHANGES", "", "agent-reviewer"); err == nil {
+		t.Error("expected a merged pull request to refuse reviews")
+	}
+	if _, err := m.FetchPR(ctx, "proj-demo", 2, false); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected PR #2 not found, got %v", err)
+	}
+	if _, err := m.PullRequests("proj-real"); err == nil {
+		t.Error("expected an unknown demo project to be not found")
+	}
+}
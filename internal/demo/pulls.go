@@ -0,0 +1,250 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Pull request states
+const (
+	PRStateOpen             = "open"
+	PRStateApproved         = "approved"
+	PRStateChangesRequested = "changes_requested"
+	PRStateMerged           = "merged"
+)
+
+// PullRequest is a demo pull request. Approving or merging one leaves main
+// alone: main keeps its seeded bugs so the demo can be replayed.
+type PullRequest struct {
+	Number       int       `json:"number"`
+	ProjectID    string    `json:"project_id"`
+	BeadID       string    `json:"bead_id"`
+	Title        string    `json:"title"`
+	Body         string    `json:"body"`
+	Base         string    `json:"base"`
+	Branch       string    `json:"branch"`
+	Diff         string    `json:"diff"`
+	State        string    `json:"state"`
+	ReviewBeadID string    `json:"review_bead_id,omitempty"`
+	Reviews      []Review  `json:"reviews"`
+	Comments     []Comment `json:"comments"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// Review is a review submitted on a demo pull request
+type Review struct {
+	ReviewerID  string    `json:"reviewer_id"`
+	Event       string    `json:"event"`
+	Body        string    `json:"body"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// Comment is a comment posted on a demo pull request, on a line of a file
+// when Path is set
+type Comment struct {
+	AuthorID  string    `json:"author_id,omitempty"`
+	Path      string    `json:"path,omitempty"`
+	Line      int       `json:"line,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// URL is where a pull request is reported to be; there is nothing served there
+func (pr *PullRequest) URL() string {
+	return fmt.Sprintf("demo://%s/pull/%d", pr.ProjectID, pr.Number)
+}
+
+// HandlesProject reports whether the project is a demo, whose pull
+// requests are kept here
+func (m *Manager) HandlesProject(projectID string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.projects[projectID]
+	return ok
+}
+
+// CreatePR opens a pull request from branch, the work directory's current
+// branch by default, into base and files a review bead scripted to approve it
+func (m *Manager) CreatePR(ctx context.Context, projectID, beadID, title, body, base, branch string) (map[string]interface{}, error) {
+	m.mu.Lock()
+	dp, ok := m.projects[projectID]
+	m.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("demo project %s not found", projectID)
+	}
+
+	if branch == "" {
+		current, err := git(ctx, dp.WorkDir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		branch = current
+	}
+	if branch == base {
+		return nil, fmt.Errorf("cannot open a pull request from %s into itself", base)
+	}
+	diff, err := git(ctx, dp.WorkDir, "diff", base+"..."+branch)
+	if err != nil {
+		return nil, err
+	}
+	if diff == "" {
+		return nil, fmt.Errorf("%s has no changes against %s", branch, base)
+	}
+
+	now := time.Now().UTC()
+	m.mu.Lock()
+	pr := &PullRequest{
+		Number:    len(m.pulls[projectID]) + 1,
+		ProjectID: projectID,
+		BeadID:    beadID,
+		Title:     title,
+		Body:      body,
+		Base:      base,
+		Branch:    branch,
+		Diff:      diff,
+		State:     PRStateOpen,
+		Reviews:   []Review{},
+		Comments:  []Comment{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	m.pulls[projectID] = append(m.pulls[projectID], pr)
+	m.mu.Unlock()
+
+	review, err := m.beads.CreateBead(
+		fmt.Sprintf("Code review: PR #%d - %s", pr.Number, title),
+		fmt.Sprintf("Review demo pull request #%d (%s into %s) for bead %s.", pr.Number, branch, base, beadID),
+		models.BeadPriorityP1,
+		"pr-review",
+		projectID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to file review bead: %w", err)
+	}
+	m.mu.Lock()
+	pr.ReviewBeadID = review.ID
+	m.scripts[review.ID] = reviewScript(review.ID, pr.Number)
+	m.mu.Unlock()
+
+	return map[string]interface{}{
+		"pr_number":      pr.Number,
+		"pr_url":         pr.URL(),
+		"branch":         branch,
+		"base":           base,
+		"review_bead_id": review.ID,
+	}, nil
+}
+
+// FetchPR returns a pull request in the shape of gh pr view's JSON
+func (m *Manager) FetchPR(ctx context.Context, projectID string, number int, includeDiff bool) (map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pr, err := m.pull(projectID, number)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]interface{}{
+		"number":      pr.Number,
+		"title":       pr.Title,
+		"body":        pr.Body,
+		"state":       pr.State,
+		"url":         pr.URL(),
+		"headRefName": pr.Branch,
+		"baseRefName": pr.Base,
+		"createdAt":   pr.CreatedAt,
+		"updatedAt":   pr.UpdatedAt,
+	}
+	if includeDiff {
+		data["diff"] = pr.Diff
+	}
+	return data, nil
+}
+
+// SubmitReview records a review. APPROVE and REQUEST_CHANGES set the pull
+// request's state; COMMENT leaves it.
+func (m *Manager) SubmitReview(ctx context.Context, projectID string, number int, event, body, reviewerID string) (map[string]interface{}, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pr, err := m.pull(projectID, number)
+	if err != nil {
+		return nil, err
+	}
+	if pr.State == PRStateMerged {
+		return nil, fmt.Errorf("pull request #%d is already merged", number)
+	}
+	switch event {
+	case "APPROVE":
+		pr.State = PRStateApproved
+	case "REQUEST_CHANGES":
+		pr.State = PRStateChangesRequested
+	case "COMMENT":
+	default:
+		return nil, fmt.Errorf("invalid review event %q", event)
+	}
+	pr.UpdatedAt = time.Now().UTC()
+	pr.Reviews = append(pr.Reviews, Review{ReviewerID: reviewerID, Event: event, Body: body, SubmittedAt: pr.UpdatedAt})
+	return map[string]interface{}{
+		"pr_number": pr.Number,
+		"event":     event,
+		"state":     pr.State,
+	}, nil
+}
+
+// AddComment posts a comment on a pull request
+func (m *Manager) AddComment(ctx context.Context, projectID string, number int, path string, line int, body, authorID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pr, err := m.pull(projectID, number)
+	if err != nil {
+		return err
+	}
+	pr.UpdatedAt = time.Now().UTC()
+	pr.Comments = append(pr.Comments, Comment{AuthorID: authorID, Path: path, Line: line, Body: body, CreatedAt: pr.UpdatedAt})
+	return nil
+}
+
+// MergePR marks an approved pull request merged
+func (m *Manager) MergePR(ctx context.Context, projectID string, number int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pr, err := m.pull(projectID, number)
+	if err != nil {
+		return err
+	}
+	if pr.State != PRStateApproved {
+		return fmt.Errorf("pull request #%d is %s, not approved", number, pr.State)
+	}
+	pr.State = PRStateMerged
+	pr.UpdatedAt = time.Now().UTC()
+	return nil
+}
+
+// PullRequests returns a demo project's pull requests, oldest first
+func (m *Manager) PullRequests(projectID string) ([]PullRequest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.projects[projectID]; !ok {
+		return nil, fmt.Errorf("demo project %s not found", projectID)
+	}
+	list := make([]PullRequest, 0, len(m.pulls[projectID]))
+	for _, pr := range m.pulls[projectID] {
+		copied := *pr
+		copied.Reviews = append([]Review{}, pr.Reviews...)
+		copied.Comments = append([]Comment{}, pr.Comments...)
+		list = append(list, copied)
+	}
+	return list, nil
+}
+
+// pull finds a pull request; the caller holds m.mu
+func (m *Manager) pull(projectID string, number int) (*PullRequest, error) {
+	list := m.pulls[projectID]
+	if number < 1 || number > len(list) {
+		return nil, fmt.Errorf("pull request #%d not found in demo project %s", number, projectID)
+	}
+	return list[number-1], nil
+}
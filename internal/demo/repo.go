@@ -0,0 +1,225 @@
+package demo
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Bug is a defect seeded into the synthetic repository, with the fix the
+// scripted coder applies
+type Bug struct {
+	Title       string
+	Description string
+	Path        string // File holding the bug
+	Fixed       string // The file once fixed
+	TestPath    string // Test file the fix extends with a regression test
+	FixedTest   string
+	Commit      string // Commit message of the fix
+}
+
+// repoFiles is the synthetic repository as first committed. Its tests pass:
+// the seeded bugs are the cases they miss.
+var repoFiles = map[string]string{
+	"go.mod": "module example.com/loom-demo\n\ngo 1.21\n",
+
+	"README.md": `# Loom Demo
+
+A synthetic repository provisioned by Loom's demo mode. It has a couple of
+seeded bugs, each filed as a bead, so you can watch agents work them from
+dispatch through commit, pull request and review. Nothing here leaves this
+machine: the remote is a local bare repository and pull requests are kept by
+Loom itself.
+`,
+
+	"AGENTS.md": `# Demo Instructions
+
+This is Loom's demo repository. Work each bead on its own agent branch,
+keep fixes small, add a regression test for every bug, and open a pull
+request against main. Run ` + "`go test ./...`" + ` before pushing.
+`,
+
+	".beads/beads/.gitkeep": "",
+
+	"textutil/reverse.go": `// Package textutil holds small string helpers.
+package textutil
+
+// Reverse returns s with its characters in reverse order.
+func Reverse(s string) string {
+	b := []byte(s)
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return string(b)
+}
+`,
+
+	"textutil/reverse_test.go": `package textutil
+
+import "testing"
+
+func TestReverse(t *testing.T) {
+	if got := Reverse("loom"); got != "mool" {
+		t.Errorf("Reverse(%q) = %q, want %q", "loom", got, "mool")
+	}
+}
+`,
+
+	"stats/stats.go": `// Package stats holds small numeric helpers.
+package stats
+
+// Average returns the integer mean of nums.
+func Average(nums []int) int {
+	sum := 0
+	for _, n := range nums {
+		sum += n
+	}
+	return sum / len(nums)
+}
+`,
+
+	"stats/stats_test.go": `package stats
+
+import "testing"
+
+func TestAverage(t *testing.T) {
+	if got := Average([]int{2, 4, 6}); got != 4 {
+		t.Errorf("Average = %d, want 4", got)
+	}
+}
+`,
+}
+
+// seededBugs are filed as beads in the order they are listed
+var seededBugs = []Bug{
+	{
+		Title: "Reverse garbles multi-byte text",
+		Description: `textutil.Reverse("héllo") returns invalid UTF-8 instead of "olléh".
+It swaps bytes rather than characters. Fix it and add a regression test.`,
+		Path: "textutil/reverse.go",
+		Fixed: `// Package textutil holds small string helpers.
+package textutil
+
+// Reverse returns s with its characters in reverse order.
+func Reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+`,
+		TestPath: "textutil/reverse_test.go",
+		FixedTest: `package textutil
+
+import "testing"
+
+func TestReverse(t *testing.T) {
+	for in, want := range map[string]string{
+		"loom":  "mool",
+		"héllo": "olléh",
+		"":      "",
+	} {
+		if got := Reverse(in); got != want {
+			t.Errorf("Reverse(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+`,
+		Commit: "textutil: reverse runes, not bytes",
+	},
+	{
+		Title: "Average panics on an empty slice",
+		Description: `stats.Average(nil) panics with an integer divide by zero. The average
+of no numbers should be 0. Fix it and add a regression test.`,
+		Path: "stats/stats.go",
+		Fixed: `// Package stats holds small numeric helpers.
+package stats
+
+// Average returns the integer mean of nums, or 0 when there are none.
+func Average(nums []int) int {
+	if len(nums) == 0 {
+		return 0
+	}
+	sum := 0
+	for _, n := range nums {
+		sum += n
+	}
+	return sum / len(nums)
+}
+`,
+		TestPath: "stats/stats_test.go",
+		FixedTest: `package stats
+
+import "testing"
+
+func TestAverage(t *testing.T) {
+	if got := Average([]int{2, 4, 6}); got != 4 {
+		t.Errorf("Average = %d, want 4", got)
+	}
+}
+
+func TestAverageEmpty(t *testing.T) {
+	if got := Average(nil); got != 0 {
+		t.Errorf("Average(nil) = %d, want 0", got)
+	}
+}
+`,
+		Commit: "stats: return 0 for the average of no numbers",
+	},
+}
+
+// initRepo writes the synthetic repository to workDir, commits it on main
+// and pushes it to a bare repository at origin
+func initRepo(ctx context.Context, workDir, origin string) error {
+	for _, dir := range []string{workDir, origin} {
+		if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+			return fmt.Errorf("%s already exists", dir)
+		}
+	}
+	for path, content := range repoFiles {
+		full := filepath.Join(workDir, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	if _, err := git(ctx, "", "init", "--bare", origin); err != nil {
+		return err
+	}
+	if _, err := git(ctx, origin, "symbolic-ref", "HEAD", "refs/heads/main"); err != nil {
+		return err
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"symbolic-ref", "HEAD", "refs/heads/main"},
+		{"config", "user.name", "Loom Demo"},
+		{"config", "user.email", "demo@loom.local"},
+		{"add", "-A"},
+		{"commit", "-m", "Initial demo repository"},
+		{"remote", "add", "origin", origin},
+		{"push", "-u", "origin", "main"},
+	} {
+		if _, err := git(ctx, workDir, args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// git runs a git command in dir and returns its trimmed output
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w\nOutput: %s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
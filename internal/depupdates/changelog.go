@@ -0,0 +1,193 @@
+package depupdates
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/onboarding"
+)
+
+const (
+	defaultGitHubAPI   = "https://api.github.com"
+	defaultNpmRegistry = "https://registry.npmjs.org"
+	// maxReleases bounds the releases summarized for one update
+	maxReleases = 10
+	// maxReleaseLine bounds the line summarizing one release
+	maxReleaseLine = 200
+)
+
+// GitHubReleases summarizes changelogs from the GitHub releases of a
+// dependency's repository: the module path of Go modules hosted on GitHub,
+// and the repository an npm package's registry entry names
+type GitHubReleases struct {
+	HTTPClient  *http.Client
+	APIURL      string // Default https://api.github.com
+	RegistryURL string // Default https://registry.npmjs.org
+	Token       string // Optional, for GitHub's higher rate limit
+}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Name       string `json:"name"`
+	Body       string `json:"body"`
+	Draft      bool   `json:"draft"`
+	Prerelease bool   `json:"prerelease"`
+}
+
+// Changelog lists the releases after d.Current up to d.Latest, newest
+// first, each with the first line of its notes. Dependencies not hosted on
+// GitHub have no changelog.
+func (g *GitHubReleases) Changelog(ctx context.Context, d onboarding.Dependency) (string, string, error) {
+	repo, err := g.repository(ctx, d)
+	if err != nil || repo == "" {
+		return "", "", err
+	}
+	releasesURL := "https://github.com/" + repo + "/releases"
+
+	var releases []githubRelease
+	if err := g.getJSON(ctx, strings.TrimRight(g.apiURL(), "/")+"/repos/"+repo+"/releases?per_page=100", true, &releases); err != nil {
+		return "", releasesURL, err
+	}
+
+	var lines []string
+	for _, r := range releases {
+		if r.Draft || (r.Prerelease && !isPrerelease(d.Latest)) {
+			continue
+		}
+		version := releaseVersion(r.TagName)
+		if compareVersions(version, d.Current) <= 0 || compareVersions(version, d.Latest) > 0 {
+			continue
+		}
+		line := "- " + r.TagName
+		if summary := firstLine(r.Body); summary != "" {
+			line += ": " + summary
+		} else if r.Name != "" && r.Name != r.TagName {
+			line += ": " + r.Name
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > maxReleases {
+		lines = append(lines[:maxReleases:maxReleases], fmt.Sprintf("- ... and %d earlier releases", len(lines)-maxReleases))
+	}
+	return strings.Join(lines, "\n"), releasesURL, nil
+}
+
+// repository returns the GitHub owner/name of d, or "" when it is not on
+// GitHub
+func (g *GitHubReleases) repository(ctx context.Context, d onboarding.Dependency) (string, error) {
+	switch d.Ecosystem {
+	case "go":
+		return githubRepo(d.Name), nil
+	case "npm":
+		var entry struct {
+			Repository json.RawMessage `json:"repository"`
+		}
+		registry := strings.TrimRight(g.registryURL(), "/")
+		if err := g.getJSON(ctx, registry+"/"+url.PathEscape(d.Name), false, &entry); err != nil {
+			return "", err
+		}
+		// The repository is either a URL or an object with one
+		var repoURL string
+		if err := json.Unmarshal(entry.Repository, &repoURL); err != nil {
+			var obj struct {
+				URL string `json:"url"`
+			}
+			_ = json.Unmarshal(entry.Repository, &obj)
+			repoURL = obj.URL
+		}
+		if strings.HasPrefix(repoURL, "github:") {
+			return strings.TrimSuffix(strings.TrimPrefix(repoURL, "github:"), ".git"), nil
+		}
+		return githubRepo(repoURL), nil
+	}
+	return "", nil
+}
+
+// githubRepo extracts owner/name from a github.com module path or URL
+func githubRepo(s string) string {
+	i := strings.Index(s, "github.com")
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimLeft(s[i+len("github.com"):], "/:")
+	parts := strings.SplitN(rest, "/", 3)
+	if len(parts) < 2 || parts[0] == "" || parts[1] == "" {
+		return ""
+	}
+	return parts[0] + "/" + strings.TrimSuffix(parts[1], ".git")
+}
+
+// releaseVersion strips a module or package prefix from a release tag,
+// e.g. "sdk/v1.2.0" or "pkg@1.2.0"
+func releaseVersion(tag string) string {
+	if i := strings.LastIndexAny(tag, "/@"); i >= 0 {
+		tag = tag[i+1:]
+	}
+	return tag
+}
+
+// firstLine returns the first line of release notes with text, skipping
+// markdown headings and dropping list markers
+func firstLine(body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimLeft(line, "*-> "))
+		if line == "" {
+			continue
+		}
+		if len(line) > maxReleaseLine {
+			line = line[:maxReleaseLine] + "..."
+		}
+		return line
+	}
+	return ""
+}
+
+func (g *GitHubReleases) getJSON(ctx context.Context, u string, github bool, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if github && g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	resp, err := g.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (g *GitHubReleases) client() *http.Client {
+	if g.HTTPClient != nil {
+		return g.HTTPClient
+	}
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+func (g *GitHubReleases) apiURL() string {
+	if g.APIURL != "" {
+		return g.APIURL
+	}
+	return defaultGitHubAPI
+}
+
+func (g *GitHubReleases) registryURL() string {
+	if g.RegistryURL != "" {
+		return g.RegistryURL
+	}
+	return defaultNpmRegistry
+}
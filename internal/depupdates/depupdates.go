@@ -0,0 +1,298 @@
+// Package depupdates finds a repository's outdated dependencies and groups
+// them by ecosystem and semver risk, so each group can be updated by one
+// bead and one pull request carrying the changelogs of the new releases.
+package depupdates
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/onboarding"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Semver risks of an update, from safest to riskiest
+const (
+	RiskPatch = "patch"
+	RiskMinor = "minor"
+	RiskMajor = "major"
+)
+
+// maxChangelog bounds the changelog summary of a single update
+const maxChangelog = 1500
+
+// riskRank orders the risks, riskiest first
+var riskRank = map[string]int{RiskMajor: 0, RiskMinor: 1, RiskPatch: 2}
+
+// Update is an outdated dependency with the risk of updating it and what
+// its new releases changed
+type Update struct {
+	onboarding.Dependency
+	Risk         string `json:"risk"`
+	Changelog    string `json:"changelog,omitempty"`     // Summary of the releases after the current one
+	ChangelogURL string `json:"changelog_url,omitempty"` // Where the full release notes are
+}
+
+// Group is the updates of one ecosystem with the same risk, made on one
+// branch and proposed in one pull request
+type Group struct {
+	Ecosystem string   `json:"ecosystem"`
+	Risk      string   `json:"risk"`
+	Updates   []Update `json:"updates"`
+}
+
+// Outcomes of filing the bead of a group
+const (
+	BeadCreated   = "created"
+	BeadUpdated   = "updated"     // The group changed since its open bead was filed
+	BeadUnchanged = "unchanged"   // The group's open bead already lists these updates
+	BeadBusy      = "in_progress" // The group's bead is being worked and is left alone
+)
+
+// FiledGroup is a group with the bead that updates it
+type FiledGroup struct {
+	Group
+	Key     string `json:"key"`
+	BeadID  string `json:"bead_id,omitempty"`
+	Outcome string `json:"outcome,omitempty"` // Empty on a dry run
+}
+
+// Report is the outcome of checking a project for dependency updates
+type Report struct {
+	ProjectID string       `json:"project_id"`
+	CheckedAt time.Time    `json:"checked_at"`
+	Groups    []FiledGroup `json:"groups"`
+	Warnings  []string     `json:"warnings,omitempty"` // Package managers and changelogs that could not be read
+	Filed     bool         `json:"filed"`
+}
+
+// Risk classifies an update by the part of the version it changes. Before
+// 1.0 every minor release may break the API, so it counts as major and a
+// patch release as minor.
+func Risk(current, latest string) string {
+	cur, next := parseVersion(current), parseVersion(latest)
+	switch {
+	case cur[0] != next[0]:
+		return RiskMajor
+	case cur[0] == 0 && cur[1] != next[1]:
+		return RiskMajor
+	case cur[0] == 0 || cur[1] != next[1]:
+		return RiskMinor
+	default:
+		return RiskPatch
+	}
+}
+
+// GroupUpdates groups deps by ecosystem and risk, ecosystems in name order
+// and the riskiest group of an ecosystem first
+func GroupUpdates(deps []onboarding.Dependency) []Group {
+	byKey := map[string]*Group{}
+	for _, d := range deps {
+		u := Update{Dependency: d, Risk: Risk(d.Current, d.Latest)}
+		key := groupKey(d.Ecosystem, u.Risk)
+		g, ok := byKey[key]
+		if !ok {
+			g = &Group{Ecosystem: d.Ecosystem, Risk: u.Risk}
+			byKey[key] = g
+		}
+		g.Updates = append(g.Updates, u)
+	}
+
+	groups := make([]Group, 0, len(byKey))
+	for _, g := range byKey {
+		sort.Slice(g.Updates, func(i, j int) bool { return g.Updates[i].Name < g.Updates[j].Name })
+		groups = append(groups, *g)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Ecosystem != groups[j].Ecosystem {
+			return groups[i].Ecosystem < groups[j].Ecosystem
+		}
+		return riskRank[groups[i].Risk] < riskRank[groups[j].Risk]
+	})
+	return groups
+}
+
+func groupKey(ecosystem, risk string) string {
+	return ecosystem + "-" + risk
+}
+
+// Key identifies the group within a project, e.g. "go-minor"
+func (g Group) Key() string {
+	return groupKey(g.Ecosystem, g.Risk)
+}
+
+// Fingerprint changes whenever a dependency joins or leaves the group or
+// a newer release comes out
+func (g Group) Fingerprint() string {
+	h := sha256.New()
+	for _, u := range g.Updates {
+		fmt.Fprintf(h, "%s@%s->%s\n", u.Name, u.Current, u.Latest)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// Priority ranks major updates, which can break the build, above the rest
+func (g Group) Priority() models.BeadPriority {
+	if g.Risk == RiskMajor {
+		return models.BeadPriorityP2
+	}
+	return models.BeadPriorityP3
+}
+
+// Title is the title of the group's bead
+func (g Group) Title() string {
+	if len(g.Updates) == 1 {
+		u := g.Updates[0]
+		return fmt.Sprintf("Update %s from %s to %s (%s)", u.Name, u.Current, u.Latest, g.Risk)
+	}
+	return fmt.Sprintf("Update %d %s dependencies (%s)", len(g.Updates), g.Ecosystem, g.Risk)
+}
+
+// Description tells the agent working the group's bead what to update and
+// how to propose it
+func (g Group) Description() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "These %s dependencies have %s releases available:\n", g.Ecosystem, g.Risk)
+	for _, u := range g.Updates {
+		fmt.Fprintf(&sb, "- %s %s -> %s\n", u.Name, u.Current, u.Latest)
+	}
+	sb.WriteString("\nUpdate them together on one branch")
+	switch g.Ecosystem {
+	case "go":
+		sb.WriteString(" with go get and go mod tidy")
+	case "npm":
+		sb.WriteString(" with npm install, keeping package-lock.json in step")
+	}
+	sb.WriteString(", build, run the tests and open one pull request. ")
+	if g.Risk == RiskMajor {
+		sb.WriteString("These releases can break the API: read their changelogs and migrate the code that uses them. ")
+	}
+	sb.WriteString("If an update cannot be made to pass, leave it out and say why in the pull request. ")
+	sb.WriteString("The changelog summary below is added to the pull request for you.\n")
+	if notes := g.PullRequestNotes(); notes != "" {
+		sb.WriteString("\n")
+		sb.WriteString(notes)
+	}
+	return sb.String()
+}
+
+// PullRequestNotes summarizes the changelogs of the group's updates for
+// the body of its pull request
+func (g Group) PullRequestNotes() string {
+	var sb strings.Builder
+	sb.WriteString("## Dependency changelogs\n")
+	for _, u := range g.Updates {
+		fmt.Fprintf(&sb, "\n### %s %s -> %s\n", u.Name, u.Current, u.Latest)
+		switch {
+		case u.Changelog != "":
+			sb.WriteString(u.Changelog)
+			sb.WriteString("\n")
+		case u.ChangelogURL == "":
+			sb.WriteString("No release notes found.\n")
+		}
+		if u.ChangelogURL != "" {
+			fmt.Fprintf(&sb, "\nFull release notes: %s\n", u.ChangelogURL)
+		}
+	}
+	return sb.String()
+}
+
+// ChangelogSource summarizes what the releases of a dependency after its
+// current version changed
+type ChangelogSource interface {
+	Changelog(ctx context.Context, d onboarding.Dependency) (summary, url string, err error)
+}
+
+// Checker finds a repository's dependency update groups
+type Checker struct {
+	Deps       onboarding.DependencyChecker
+	Changelogs ChangelogSource // Nil leaves the groups without changelogs
+}
+
+// Check lists the update groups of the repository checked out in dir.
+// Package managers and changelogs that cannot be read are reported as
+// warnings, leaving out what they would have found.
+func (c *Checker) Check(ctx context.Context, dir string) ([]Group, []string, error) {
+	if c.Deps == nil {
+		return nil, nil, fmt.Errorf("no dependency checker configured")
+	}
+	var warnings []string
+	deps, err := c.Deps.Outdated(ctx, dir)
+	if err != nil {
+		if len(deps) == 0 {
+			return nil, nil, err
+		}
+		warnings = append(warnings, err.Error())
+	}
+
+	groups := GroupUpdates(deps)
+	if c.Changelogs == nil {
+		return groups, warnings, nil
+	}
+	for gi := range groups {
+		for ui := range groups[gi].Updates {
+			u := &groups[gi].Updates[ui]
+			summary, url, err := c.Changelogs.Changelog(ctx, u.Dependency)
+			if err != nil {
+				warnings = append(warnings, fmt.Sprintf("changelog of %s: %v", u.Name, err))
+				continue
+			}
+			u.Changelog, u.ChangelogURL = truncate(summary, maxChangelog), url
+		}
+	}
+	return groups, warnings, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.TrimRight(s[:n], " \n") + "\n..."
+}
+
+// parseVersion reads the major, minor and patch numbers of a version such
+// as v1.2.3, ^1.2.3 or 1.2.3-rc.1. Missing or unreadable numbers are 0.
+func parseVersion(v string) [3]int {
+	v = strings.TrimLeft(strings.TrimSpace(v), "^~>=v ")
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	var parts [3]int
+	for i, p := range strings.SplitN(v, ".", 3) {
+		parts[i], _ = strconv.Atoi(p)
+	}
+	return parts
+}
+
+// compareVersions returns -1, 0 or 1 as a is older than, the same as or
+// newer than b. A pre-release is older than its release.
+func compareVersions(a, b string) int {
+	va, vb := parseVersion(a), parseVersion(b)
+	for i := range va {
+		if va[i] != vb[i] {
+			if va[i] < vb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	preA, preB := isPrerelease(a), isPrerelease(b)
+	switch {
+	case preA && !preB:
+		return -1
+	case preB && !preA:
+		return 1
+	}
+	return 0
+}
+
+func isPrerelease(v string) bool {
+	v, _, _ = strings.Cut(v, "+")
+	return strings.Contains(v, "-")
+}
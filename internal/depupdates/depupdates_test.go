@@ -0,0 +1,128 @@
+package depupdates
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/onboarding"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestRisk(t *testing.T) {
+	cases := []struct{ current, latest, want string }{
+		{"v1.2.3", "v1.2.4", RiskPatch},
+		{"v1.2.3", "v1.3.0", RiskMinor},
+		{"1.2.3", "2.0.0", RiskMajor},
+		{"^4.17.20", "4.17.21", RiskPatch},
+		{"v0.3.1", "v0.4.0", RiskMajor},
+		{"v0.3.1", "v0.3.2", RiskMinor},
+		{"v1.2.3", "v1.2.4-rc.1", RiskPatch},
+	}
+	for _, c := range cases {
+		if got := Risk(c.current, c.latest); got != c.want {
+			t.Errorf("Risk(%s, %s) = %s, want %s", c.current, c.latest, got, c.want)
+		}
+	}
+}
+
+func TestGroupUpdates(t *testing.T) {
+	groups := GroupUpdates([]onboarding.Dependency{
+		{Ecosystem: "npm", Name: "react", Current: "17.0.2", Latest: "18.2.0"},
+		{Ecosystem: "go", Name: "github.com/z/z", Current: "v1.0.0", Latest: "v1.0.1"},
+		{Ecosystem: "go", Name: "github.com/b/b", Current: "v1.1.0", Latest: "v1.2.0"},
+		{Ecosystem: "go", Name: "github.com/a/a", Current: "v1.0.0", Latest: "v1.0.3"},
+	})
+	var keys []string
+	for _, g := range groups {
+		keys = append(keys, g.Key())
+	}
+	if strings.Join(keys, ",") != "go-minor,go-patch,npm-major" {
+		t.Fatalf("groups = %v", keys)
+	}
+	patch := groups[1]
+	if len(patch.Updates) != 2 || patch.Updates[0].Name != "github.com/a/a" {
+		t.Errorf("patch group = %+v, want both updates in name order", patch.Updates)
+	}
+	if patch.Title() != "Update 2 go dependencies (patch)" || patch.Priority() != models.BeadPriorityP3 {
+		t.Errorf("patch group = %q P%d", patch.Title(), patch.Priority())
+	}
+	major := groups[2]
+	if major.Title() != "Update react from 17.0.2 to 18.2.0 (major)" || major.Priority() != models.BeadPriorityP2 {
+		t.Errorf("major group = %q P%d", major.Title(), major.Priority())
+	}
+	if !strings.Contains(major.Description(), "migrate the code") {
+		t.Errorf("a major group's description must ask for migration:\n%s", major.Description())
+	}
+
+	before := patch.Fingerprint()
+	patch.Updates[1].Latest = "v1.0.2"
+	if patch.Fingerprint() == before {
+		t.Error("a newer release must change the fingerprint")
+	}
+}
+
+type fakeDeps struct {
+	deps []onboarding.Dependency
+	err  error
+}
+
+func (f fakeDeps) Outdated(ctx context.Context, dir string) ([]onboarding.Dependency, error) {
+	return f.deps, f.err
+}
+
+func TestCheck_Changelogs(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/o/lib/releases":
+			w.Write([]byte(`[
+				{"tag_name": "v1.4.0-rc.1", "prerelease": true, "body": "Release candidate"},
+				{"tag_name": "v1.3.0", "body": "## Highlights\n\n* Adds streaming\n* Fixes leaks"},
+				{"tag_name": "v1.2.1", "name": "Security fix"},
+				{"tag_name": "v1.2.0", "body": "Current release"},
+				{"tag_name": "v1.1.0", "body": "Old"}
+			]`))
+		case "/left-pad":
+			w.Write([]byte(`{"repository": {"type": "git", "url": "git+https://github.com/o/left-pad.git"}}`))
+		case "/repos/o/left-pad/releases":
+			http.Error(w, "rate limited", http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	checker := &Checker{
+		Deps: fakeDeps{deps: []onboarding.Dependency{
+			{Ecosystem: "go", Name: "github.com/o/lib", Current: "v1.2.0", Latest: "v1.3.0"},
+			{Ecosystem: "go", Name: "example.com/other", Current: "v1.0.0", Latest: "v1.1.0"},
+			{Ecosystem: "npm", Name: "left-pad", Current: "1.0.0", Latest: "1.0.1"},
+		}, err: errors.New("pip: not found")},
+		Changelogs: &GitHubReleases{APIURL: srv.URL, RegistryURL: srv.URL},
+	}
+	groups, warnings, err := checker.Check(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("groups = %+v", groups)
+	}
+	lib := groups[0].Updates[1]
+	if lib.Name != "github.com/o/lib" || lib.Changelog != "- v1.3.0: Adds streaming\n- v1.2.1: Security fix" || lib.ChangelogURL != "https://github.com/o/lib/releases" {
+		t.Errorf("lib update = %+v", lib)
+	}
+	notes := groups[0].PullRequestNotes()
+	if !strings.Contains(notes, "### example.com/other v1.0.0 -> v1.1.0\nNo release notes found.") || !strings.Contains(notes, "Full release notes: https://github.com/o/lib/releases") {
+		t.Errorf("pull request notes:\n%s", notes)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[1], "left-pad") {
+		t.Errorf("warnings = %v, want the package manager and changelog failures", warnings)
+	}
+
+	if _, _, err := (&Checker{Deps: fakeDeps{err: errors.New("go: not found")}}).Check(context.Background(), t.TempDir()); err == nil {
+		t.Error("expected an error when no package manager could run")
+	}
+}
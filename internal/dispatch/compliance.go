@@ -0,0 +1,76 @@
+package dispatch
+
+import (
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// compliantProvider picks the provider for a dispatch into a project of
+// organization orgID with compliance constraints: the current provider if
+// the constraints and organization allow it, otherwise the best allowed
+// provider for the task's complexity, falling back to any allowed active
+// provider. It reports false when none is allowed.
+func (d *Dispatcher) compliantProvider(current string, complexity provider.ComplexityLevel, c *models.ComplianceConstraints, orgID string) (string, bool) {
+	if current != "" && d.providers.IsActive(current) {
+		if p, err := d.providers.Get(current); err == nil && allowsProvider(c, p.Config.Tags) && servesOrg(p.Config.OrgID, orgID) {
+			return current, true
+		}
+	}
+	for _, candidates := range [][]*provider.RegisteredProvider{
+		d.providers.ListActiveForComplexity(complexity),
+		d.providers.ListActive(),
+	} {
+		for _, p := range candidates {
+			if p != nil && p.Config != nil && allowsProvider(c, p.Config.Tags) && servesOrg(p.Config.OrgID, orgID) {
+				return p.Config.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// ProviderServesProject reports whether a provider may serve a project of
+// organization projectOrg with compliance constraints c, outside a dispatch
+func ProviderServesProject(cfg *provider.ProviderConfig, c *models.ComplianceConstraints, projectOrg string) bool {
+	return cfg != nil && allowsProvider(c, cfg.Tags) && servesOrg(cfg.OrgID, projectOrg)
+}
+
+// allowsProvider reports whether a provider carrying tags may serve a project
+// with constraints c. The demo mode's scripted mock only serves the demo
+// projects that require it, never a real project.
+func allowsProvider(c *models.ComplianceConstraints, tags []string) bool {
+	if !c.AllowsProvider(tags) {
+		return false
+	}
+	return !hasTag(tags, provider.DemoTag) || (c != nil && hasTag(c.RequiredProviderTags, provider.DemoTag))
+}
+
+// servesOrg reports whether a provider reserved for providerOrg may serve a
+// project of organization projectOrg. Shared providers serve every
+// organization.
+func servesOrg(providerOrg, projectOrg string) bool {
+	return providerOrg == "" || providerOrg == org.Normalize(projectOrg)
+}
+
+// foreignProvider reports whether a provider is reserved for an
+// organization other than projectOrg
+func (d *Dispatcher) foreignProvider(providerID, projectOrg string) bool {
+	p, err := d.providers.Get(providerID)
+	return err == nil && p.Config != nil && !servesOrg(p.Config.OrgID, projectOrg)
+}
+
+// demoProvider reports whether a provider is the demo mode's scripted mock
+func (d *Dispatcher) demoProvider(providerID string) bool {
+	p, err := d.providers.Get(providerID)
+	return err == nil && p.Config != nil && hasTag(p.Config.Tags, provider.DemoTag)
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package dispatch
+
+import (
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestCompliantProvider(t *testing.T) {
+	registry := provider.NewRegistry()
+	for _, cfg := range []*provider.ProviderConfig{
+		{ID: "us-big", Type: "openai", Endpoint: "http://localhost:8000/v1", Model: "m", Status: "active", ModelParamsB: 400, Tags: []string{"us-hosted"}},
+		{ID: "eu-small", Type: "openai", Endpoint: "http://localhost:8001/v1", Model: "m", Status: "active", ModelParamsB: 8, Tags: []string{"eu-hosted", "gdpr"}},
+	} {
+		if err := registry.Register(cfg); err != nil {
+			t.Fatalf("Register %s: %v", cfg.ID, err)
+		}
+	}
+	d := NewDispatcher(nil, nil, nil, registry, nil)
+	eu := &models.ComplianceConstraints{RequiredProviderTags: []string{"eu-hosted"}}
+
+	if id, ok := d.compliantProvider("us-big", provider.ComplexityComplex, eu, ""); !ok || id != "eu-small" {
+		t.Errorf("expected a non-compliant provider to be replaced by eu-small, got %q (%v)", id, ok)
+	}
+	if id, ok := d.compliantProvider("eu-small", provider.ComplexityComplex, eu, ""); !ok || id != "eu-small" {
+		t.Errorf("expected a compliant provider to be kept, got %q (%v)", id, ok)
+	}
+
+	onPrem := &models.ComplianceConstraints{RequiredProviderTags: []string{"on-prem"}}
+	if id, ok := d.compliantProvider("us-big", provider.ComplexityMedium, onPrem, ""); ok {
+		t.Errorf("expected no provider to satisfy on-prem, got %q", id)
+	}
+}
+
+func TestCompliantProvider_DemoOnlyServesDemoProjects(t *testing.T) {
+	registry := provider.NewRegistry()
+	registry.UpsertProtocol(&provider.ProviderConfig{
+		ID: "demo-mock", Type: "mock", Status: "active", ModelParamsB: 1000, Tags: []string{provider.DemoTag},
+	}, provider.NewMockProvider())
+	if err := registry.Register(&provider.ProviderConfig{
+		ID: "real", Type: "openai", Endpoint: "http://localhost:8000/v1", Model: "m", Status: "active", ModelParamsB: 8,
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	d := NewDispatcher(nil, nil, nil, registry, nil)
+
+	if !d.demoProvider("demo-mock") || d.demoProvider("real") {
+		t.Fatal("expected only demo-mock to be the demo provider")
+	}
+	if id, ok := d.compliantProvider("demo-mock", provider.ComplexityMedium, nil, ""); !ok || id != "real" {
+		t.Errorf("expected a real project to be moved off the demo provider, got %q (%v)", id, ok)
+	}
+	demo := &models.ComplianceConstraints{RequiredProviderTags: []string{provider.DemoTag}}
+	if id, ok := d.compliantProvider("real", provider.ComplexityMedium, demo, ""); !ok || id != "demo-mock" {
+		t.Errorf("expected a demo project to be routed to demo-mock, got %q (%v)", id, ok)
+	}
+}
+
+func TestCompliantProvider_ReservedProvidersServeTheirOrganization(t *testing.T) {
+	registry := provider.NewRegistry()
+	for _, cfg := range []*provider.ProviderConfig{
+		{ID: "acme-only", Type: "openai", Endpoint: "http://localhost:8000/v1", Model: "m", Status: "active", ModelParamsB: 400, OrgID: "acme"},
+		{ID: "shared", Type: "openai", Endpoint: "http://localhost:8001/v1", Model: "m", Status: "active", ModelParamsB: 8},
+	} {
+		if err := registry.Register(cfg); err != nil {
+			t.Fatalf("Register %s: %v", cfg.ID, err)
+		}
+	}
+	d := NewDispatcher(nil, nil, nil, registry, nil)
+
+	if !d.foreignProvider("acme-only", "") || d.foreignProvider("acme-only", "acme") || d.foreignProvider("shared", "globex") {
+		t.Fatal("expected acme-only to be foreign to every organization but acme, and shared to none")
+	}
+	if id, ok := d.compliantProvider("acme-only", provider.ComplexityComplex, nil, ""); !ok || id != "shared" {
+		t.Errorf("expected a default organization project to be moved to the shared provider, got %q (%v)", id, ok)
+	}
+	if id, ok := d.compliantProvider("acme-only", provider.ComplexityComplex, nil, "acme"); !ok || id != "acme-only" {
+		t.Errorf("expected acme's project to keep acme's provider, got %q (%v)", id, ok)
+	}
+}
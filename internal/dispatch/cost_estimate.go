@@ -0,0 +1,86 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/costestimate"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// BeadCostEstimateKey is the bead context key holding the estimated
+	// cost in USD of the bead's latest dispatch
+	BeadCostEstimateKey = "cost_estimate_usd"
+	// BeadCostReasonKey holds why the latest estimate held the bead back
+	BeadCostReasonKey = "cost_estimate_reason"
+	// BeadCostApprovedKey, set to "true", lets a bead whose estimate is over
+	// the per-dispatch limit be dispatched anyway
+	BeadCostApprovedKey = "cost_estimate_approved"
+	// BeadCostHeldUntilKey holds back a bead whose estimate was more than
+	// the remaining budget until the given RFC3339 time
+	BeadCostHeldUntilKey = "cost_estimate_held_until"
+)
+
+// costHoldDuration is how long a bead estimated at more than the remaining
+// budget waits before it is estimated again
+const costHoldDuration = 15 * time.Minute
+
+// costEstimateAllows acts on a dispatch's estimate. A warning is logged and
+// the dispatch goes ahead. An estimate over the per-dispatch limit blocks
+// the bead until someone approves it, and one over the remaining budget
+// holds it back for costHoldDuration.
+func (d *Dispatcher) costEstimateAllows(ctx context.Context, b *models.Bead, est *costestimate.Estimate) bool {
+	switch est.Action {
+	case costestimate.ActionWarn:
+		dispatchLog.WarnContext(ctx, "dispatch cost estimate over a warning threshold",
+			"estimate_usd", est.CostUSD, "estimated_tokens", est.Tokens, "reason", est.Reason)
+		return true
+
+	case costestimate.ActionBlock:
+		if b.Context[BeadCostApprovedKey] == "true" {
+			dispatchLog.InfoContext(ctx, "dispatch cost estimate over the limit was approved",
+				"estimate_usd", est.CostUSD, "reason", est.Reason)
+			return true
+		}
+		dispatchLog.WarnContext(ctx, "dispatch cost estimate over the limit, blocking bead",
+			"estimate_usd", est.CostUSD, "estimated_tokens", est.Tokens, "reason", est.Reason)
+		updates := map[string]interface{}{
+			"status": models.BeadStatusBlocked,
+			"context": map[string]string{
+				BeadCostEstimateKey: fmt.Sprintf("%.4f", est.CostUSD),
+				BeadCostReasonKey:   est.Reason,
+			},
+		}
+		if err := d.beads.UpdateBead(b.ID, updates); err != nil {
+			dispatchLog.ErrorContext(ctx, "failed to block bead over its cost estimate", "error", err)
+		}
+		if d.eventBus != nil {
+			_ = d.eventBus.PublishBeadEvent(eventbus.EventTypeBeadStatusChange, b.ID, b.ProjectID,
+				map[string]interface{}{
+					"status":       string(models.BeadStatusBlocked),
+					"cost_reason":  est.Reason,
+					"estimate_usd": est.CostUSD,
+				})
+		}
+		return false
+
+	case costestimate.ActionHold:
+		dispatchLog.InfoContext(ctx, "dispatch cost estimate over the remaining budget, holding bead back",
+			"estimate_usd", est.CostUSD, "reason", est.Reason, "for", costHoldDuration.String())
+		updates := map[string]interface{}{
+			"context": map[string]string{
+				BeadCostEstimateKey:  fmt.Sprintf("%.4f", est.CostUSD),
+				BeadCostReasonKey:    est.Reason,
+				BeadCostHeldUntilKey: time.Now().Add(costHoldDuration).UTC().Format(time.RFC3339),
+			},
+		}
+		if err := d.beads.UpdateBead(b.ID, updates); err != nil {
+			dispatchLog.ErrorContext(ctx, "failed to hold bead over its cost estimate", "error", err)
+		}
+		return false
+	}
+	return true
+}
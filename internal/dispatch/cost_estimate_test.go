@@ -0,0 +1,66 @@
+package dispatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/beads"
+	"github.com/jordanhubbard/loom/internal/costestimate"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestCostEstimateAllows(t *testing.T) {
+	beadsMgr := beads.NewManager("")
+	beadsMgr.SetBeadsPath(t.TempDir())
+	d := &Dispatcher{beads: beadsMgr}
+	ctx := context.Background()
+
+	newBead := func() *models.Bead {
+		b, err := beadsMgr.CreateBead("Rewrite the scheduler", "", models.BeadPriorityP2, "task", "proj-1")
+		if err != nil {
+			t.Fatalf("CreateBead failed: %v", err)
+		}
+		return b
+	}
+
+	b := newBead()
+	if !d.costEstimateAllows(ctx, b, &costestimate.Estimate{Action: costestimate.ActionAllow}) {
+		t.Error("expected an allowed estimate to dispatch")
+	}
+	if !d.costEstimateAllows(ctx, b, &costestimate.Estimate{Action: costestimate.ActionWarn, Reason: "pricey"}) {
+		t.Error("expected a warning to dispatch")
+	}
+
+	// Over the per-dispatch limit blocks the bead
+	if d.costEstimateAllows(ctx, b, &costestimate.Estimate{Action: costestimate.ActionBlock, CostUSD: 12.5, Reason: "over the limit"}) {
+		t.Fatal("expected an estimate over the limit to hold the dispatch")
+	}
+	blocked, _ := beadsMgr.GetBead(b.ID)
+	if blocked.Status != models.BeadStatusBlocked {
+		t.Errorf("expected the bead to be blocked, got %s", blocked.Status)
+	}
+	if blocked.Context[BeadCostEstimateKey] != "12.5000" || blocked.Context[BeadCostReasonKey] != "over the limit" {
+		t.Errorf("expected the estimate on the bead, got %v", blocked.Context)
+	}
+
+	// ...unless it was approved
+	blocked.Context[BeadCostApprovedKey] = "true"
+	if !d.costEstimateAllows(ctx, blocked, &costestimate.Estimate{Action: costestimate.ActionBlock, CostUSD: 12.5}) {
+		t.Error("expected an approved bead to dispatch over the limit")
+	}
+
+	// Over the remaining budget holds the bead back for a while
+	held := newBead()
+	if d.costEstimateAllows(ctx, held, &costestimate.Estimate{Action: costestimate.ActionHold, CostUSD: 3, Reason: "over budget"}) {
+		t.Fatal("expected an estimate over the remaining budget to hold the dispatch")
+	}
+	held, _ = beadsMgr.GetBead(held.ID)
+	until, err := time.Parse(time.RFC3339, held.Context[BeadCostHeldUntilKey])
+	if err != nil || !until.After(time.Now()) {
+		t.Errorf("expected the bead held until a later time, got %q", held.Context[BeadCostHeldUntilKey])
+	}
+	if held.Status == models.BeadStatusBlocked {
+		t.Error("expected a held bead not to be blocked")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
@@ -14,9 +13,14 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/agent"
+	"github.com/jordanhubbard/loom/internal/artifacts"
 	"github.com/jordanhubbard/loom/internal/beads"
+	"github.com/jordanhubbard/loom/internal/costestimate"
 	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/logging"
+	"github.com/jordanhubbard/loom/internal/metrics"
 	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/persona"
 	"github.com/jordanhubbard/loom/internal/project"
 	"github.com/jordanhubbard/loom/internal/provider"
 	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
@@ -25,6 +29,10 @@ import (
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
+// dispatchLog is the structured logger of dispatch, commit serialization
+// and bead workflows
+var dispatchLog = logging.Logger("dispatch")
+
 type StatusState string
 
 const (
@@ -65,13 +73,22 @@ type Dispatcher struct {
 	eventBus            *eventbus.EventBus
 	workflowEngine      *workflow.Engine
 	personaMatcher      *PersonaMatcher
+	fileExpertise       *FileExpertiseProvider
+	artifacts           *artifacts.Recorder
+	repoSummarizer      *RepoSummarizer
 	autoBugRouter       *AutoBugRouter
 	complexityEstimator *provider.ComplexityEstimator
 	readinessCheck      func(context.Context, string) (bool, []string)
 	readinessMode       ReadinessMode
 	escalator           Escalator
+	policy              PolicyGate
+	costs               CostEstimator
+	planApprover        PlanApprover
+	personas            PersonaSource
+	judge               OutputJudge
 	maxDispatchHops     int
 	loopDetector        *LoopDetector
+	metrics             *metrics.Metrics
 
 	// Commit serialization (Gap #2)
 	commitLock        sync.Mutex        // Global commit lock
@@ -80,8 +97,9 @@ type Dispatcher struct {
 	commitInProgress  *commitState      // Current commit state
 	commitStateMutex  sync.RWMutex      // Protects commitInProgress
 
-	mu     sync.RWMutex
-	status SystemStatus
+	mu        sync.RWMutex
+	status    SystemStatus
+	saturated map[string]bool // Providers whose inference server was saturated at the last dispatch pass
 }
 
 // commitRequest represents a request to acquire the commit lock
@@ -105,6 +123,28 @@ type Escalator interface {
 	EscalateBeadToCEO(beadID, reason, returnedTo string) (*models.DecisionBead, error)
 }
 
+// PolicyGate applies the arbiter's budget and escalation policies to
+// dispatch.
+type PolicyGate interface {
+	// DispatchAllowed reports whether the budget policy lets the bead be
+	// dispatched, and why not
+	DispatchAllowed(ctx context.Context, b *models.Bead) (bool, string)
+	// EscalateStuckBead reports whether a bead stuck in a loop over the
+	// dispatch limit goes to the CEO instead of being blocked, and why
+	EscalateStuckBead(ctx context.Context, b *models.Bead, dispatchCount int, loopReason string) (bool, string)
+}
+
+// CostEstimator predicts what a dispatch will cost before it starts and
+// learns from what it did cost
+type CostEstimator interface {
+	// EstimateDispatch estimates a dispatch and decides whether it may go
+	// ahead
+	EstimateDispatch(ctx context.Context, req costestimate.Request) (*costestimate.Estimate, error)
+	// CompleteDispatch records the tokens, iterations and cost a dispatch
+	// took
+	CompleteDispatch(ctx context.Context, taskID string, tokens int64, iterations int, costUSD float64)
+}
+
 func NewDispatcher(beadsMgr *beads.Manager, projMgr *project.Manager, agentMgr *agent.WorkerManager, registry *provider.Registry, eb *eventbus.EventBus) *Dispatcher {
 	d := &Dispatcher{
 		beads:               beadsMgr,
@@ -116,6 +156,7 @@ func NewDispatcher(beadsMgr *beads.Manager, projMgr *project.Manager, agentMgr *
 		autoBugRouter:       NewAutoBugRouter(),
 		complexityEstimator: provider.NewComplexityEstimator(),
 		loopDetector:        NewLoopDetector(),
+		repoSummarizer:      NewRepoSummarizer(),
 		readinessMode:       ReadinessWarn,
 		commitQueue:         make(chan commitRequest, 100), // Buffer 100 waiting commits
 		commitLockTimeout:   5 * time.Minute,
@@ -152,6 +193,28 @@ func (d *Dispatcher) SetWorkflowEngine(engine *workflow.Engine) {
 	d.workflowEngine = engine
 }
 
+// SetFileExpertise enables routing beads that mention files to agents
+// that have successfully modified them before
+func (d *Dispatcher) SetFileExpertise(fp *FileExpertiseProvider) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fileExpertise = fp
+}
+
+// SetArtifactRecorder enables recording each dispatch's diff as a run artifact
+func (d *Dispatcher) SetArtifactRecorder(r *artifacts.Recorder) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.artifacts = r
+}
+
+// SetMetrics enables recording dispatch latency and loop detector trips
+func (d *Dispatcher) SetMetrics(m *metrics.Metrics) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.metrics = m
+}
+
 // SetEscalator sets the escalator used for CEO escalation.
 func (d *Dispatcher) SetEscalator(escalator Escalator) {
 	d.mu.Lock()
@@ -159,6 +222,50 @@ func (d *Dispatcher) SetEscalator(escalator Escalator) {
 	d.escalator = escalator
 }
 
+// SetPolicyGate makes dispatch follow the arbiter's budget and escalation
+// policies
+func (d *Dispatcher) SetPolicyGate(gate PolicyGate) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.policy = gate
+}
+
+// SetCostEstimator makes dispatch estimate what each dispatch will cost
+// before it starts, holding back dispatches over the cost thresholds
+func (d *Dispatcher) SetCostEstimator(costs CostEstimator) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.costs = costs
+}
+
+// OutputJudge grades the work of dispatches that finished with changes
+type OutputJudge interface {
+	// JudgeDispatch grades what the agent answered and changed for the
+	// bead; it must not block dispatch
+	JudgeDispatch(b *models.Bead, dispatchID, agentID, providerID string, result *worker.TaskResult, diff string)
+}
+
+// SetOutputJudge makes every dispatch that finishes its work with changes
+// be graded by the project's judges
+func (d *Dispatcher) SetOutputJudge(judge OutputJudge) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.judge = judge
+}
+
+// PersonaSource resolves a persona with its published definition
+type PersonaSource interface {
+	LoadPersona(name string) (*models.Persona, error)
+}
+
+// SetPersonas lets dispatch follow the model tier an agent's persona
+// prefers
+func (d *Dispatcher) SetPersonas(personas PersonaSource) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.personas = personas
+}
+
 // SetMaxDispatchHops configures the max hop limit before escalation.
 func (d *Dispatcher) SetMaxDispatchHops(maxHops int) {
 	d.mu.Lock()
@@ -196,7 +303,7 @@ func (d *Dispatcher) processCommitQueue() {
 		}
 		d.commitStateMutex.Unlock()
 
-		log.Printf("[Commit] Processing commit for bead %s (agent %s)", req.BeadID, req.AgentID)
+		dispatchLog.Info("processing commit", "bead_id", req.BeadID, "agent_id", req.AgentID)
 
 		// Signal that lock is acquired (requester can proceed with commit)
 		req.ResultCh <- nil
@@ -212,8 +319,8 @@ func (d *Dispatcher) acquireCommitLock(ctx context.Context, beadID, agentID stri
 	if d.commitInProgress != nil {
 		elapsed := time.Since(d.commitInProgress.StartedAt)
 		if elapsed > d.commitLockTimeout {
-			log.Printf("[Commit] WARNING: Previous commit by agent %s timed out after %v, forcibly releasing lock",
-				d.commitInProgress.AgentID, elapsed)
+			dispatchLog.WarnContext(ctx, "previous commit timed out, forcibly releasing commit lock",
+				"holder_agent_id", d.commitInProgress.AgentID, "elapsed", elapsed)
 			d.commitStateMutex.RUnlock()
 			d.releaseCommitLock()
 		} else {
@@ -233,7 +340,7 @@ func (d *Dispatcher) acquireCommitLock(ctx context.Context, beadID, agentID stri
 
 	select {
 	case d.commitQueue <- req:
-		log.Printf("[Commit] Bead %s queued for commit (agent %s)", beadID, agentID)
+		dispatchLog.InfoContext(ctx, "bead queued for commit", "bead_id", beadID, "agent_id", agentID)
 	case <-ctx.Done():
 		return fmt.Errorf("context cancelled while waiting for commit queue")
 	}
@@ -251,8 +358,8 @@ func (d *Dispatcher) acquireCommitLock(ctx context.Context, beadID, agentID stri
 func (d *Dispatcher) releaseCommitLock() {
 	d.commitStateMutex.Lock()
 	if d.commitInProgress != nil {
-		log.Printf("[Commit] Releasing commit lock for bead %s (held for %v)",
-			d.commitInProgress.BeadID, time.Since(d.commitInProgress.StartedAt))
+		dispatchLog.Info("releasing commit lock",
+			"bead_id", d.commitInProgress.BeadID, "held", time.Since(d.commitInProgress.StartedAt))
 		d.commitInProgress = nil
 	}
 	d.commitStateMutex.Unlock()
@@ -263,12 +370,14 @@ func (d *Dispatcher) releaseCommitLock() {
 // DispatchOnce finds at most one ready bead and asks an idle agent to work on it.
 func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*DispatchResult, error) {
 	activeProviders := d.providers.ListActive()
-	log.Printf("[Dispatcher] DispatchOnce called for project=%s, active_providers=%d", projectID, len(activeProviders))
+	ctx = logging.WithFields(ctx, "project_id", projectID)
+	dispatchLog.DebugContext(ctx, "dispatch pass", "active_providers", len(activeProviders))
 	if len(activeProviders) == 0 {
-		log.Printf("[Dispatcher] Parked - no active providers")
+		dispatchLog.InfoContext(ctx, "parked: no active providers")
 		d.setStatus(StatusParked, "no active providers registered")
 		return &DispatchResult{Dispatched: false, ProjectID: projectID}, nil
 	}
+	d.reportSaturation(activeProviders)
 
 	ready, err := d.beads.GetReadyBeads(projectID)
 	if err != nil {
@@ -328,7 +437,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		}
 	}
 
-	log.Printf("[Dispatcher] GetReadyBeads returned %d beads for project %s", len(ready), projectID)
+	dispatchLog.DebugContext(ctx, "ready beads listed", "ready", len(ready))
 
 	sort.SliceStable(ready, func(i, j int) bool {
 		if ready[i] == nil {
@@ -363,8 +472,9 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			if len(activeProviders) > 0 {
 				best := activeProviders[0]
 				candidateAgent.ProviderID = best.Config.ID
-				log.Printf("[Dispatcher] Auto-assigned default provider %s (score=%.0f, latency=%dms) to agent %s",
-					best.Config.ID, best.Config.CapabilityScore, best.Config.LastHeartbeatLatencyMs, candidateAgent.Name)
+				dispatchLog.InfoContext(ctx, "auto-assigned default provider to agent",
+					"agent_id", candidateAgent.ID, "provider_id", best.Config.ID,
+					"score", best.Config.CapabilityScore, "latency_ms", best.Config.LastHeartbeatLatencyMs)
 			} else {
 				continue
 			}
@@ -372,7 +482,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		// Promote paused agents to idle now that they have a provider.
 		if candidateAgent.Status == "paused" {
 			candidateAgent.Status = "idle"
-			log.Printf("[Dispatcher] Promoted agent %s from paused to idle", candidateAgent.Name)
+			dispatchLog.InfoContext(ctx, "promoted agent from paused to idle", "agent_id", candidateAgent.ID)
 		}
 		filteredAgents = append(filteredAgents, candidateAgent)
 	}
@@ -397,20 +507,21 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		// These should be handled manually or escalated to CEO, not auto-assigned to agents
 		if d.hasTag(b, "requires-human-config") {
 			skippedReasons["requires_human_config"]++
-			log.Printf("[Dispatcher] Skipping bead %s: requires human configuration", b.ID)
+			dispatchLog.DebugContext(ctx, "skipping bead that requires human configuration", "bead_id", b.ID)
 			continue
 		}
 
 		// Check if this is an auto-filed bug that needs routing
 		if routeInfo := d.autoBugRouter.AnalyzeBugForRouting(b); routeInfo.ShouldRoute {
-			log.Printf("[Dispatcher] Auto-bug detected: %s - routing to %s (%s)", b.ID, routeInfo.PersonaHint, routeInfo.RoutingReason)
+			dispatchLog.InfoContext(ctx, "auto-filed bug routed", "bead_id", b.ID,
+				"persona_hint", routeInfo.PersonaHint, "reason", routeInfo.RoutingReason)
 
 			// Update the bead with persona hint in title
 			updates := map[string]interface{}{
 				"title": routeInfo.UpdatedTitle,
 			}
 			if err := d.beads.UpdateBead(b.ID, updates); err != nil {
-				log.Printf("[Dispatcher] Failed to update bead %s with persona hint: %v", b.ID, err)
+				dispatchLog.ErrorContext(ctx, "failed to update bead with persona hint", "bead_id", b.ID, "error", err)
 			} else {
 				// Refresh the bead to get updated title
 				b.Title = routeInfo.UpdatedTitle
@@ -434,7 +545,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 				b.Context["redispatch_requested"] = "true"
 				b.Context["redispatch_requested_at"] = time.Now().UTC().Format(time.RFC3339)
 				if err := d.beads.UpdateBead(b.ID, map[string]interface{}{"context": b.Context}); err != nil {
-					log.Printf("[Dispatcher] Failed to auto-enable redispatch for bead %s: %v", b.ID, err)
+					dispatchLog.ErrorContext(ctx, "failed to auto-enable redispatch", "bead_id", b.ID, "error", err)
 				}
 			}
 		}
@@ -462,16 +573,34 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 
 			if !stuck {
 				// Making progress - allow to continue beyond hop limit
-				log.Printf("[Dispatcher] Bead %s has %d dispatches but is making progress, allowing to continue. Progress: %s",
-					b.ID, dispatchCount, d.loopDetector.GetProgressSummary(b))
+				dispatchLog.InfoContext(ctx, "bead over the dispatch limit is making progress, allowing it to continue",
+					"bead_id", b.ID, "dispatch_count", dispatchCount, "progress", d.loopDetector.GetProgressSummary(b))
 				skippedReasons["dispatch_limit_but_progressing"]++
 				// Don't continue - allow this bead to be dispatched
 			} else {
-				// Ralph auto-block: stuck in loop — block autonomously instead of CEO escalation
+				if d.metrics != nil {
+					d.metrics.RecordLoopTrip(b.ProjectID, "progress")
+				}
 				reason := fmt.Sprintf("dispatch_count=%d exceeded max_hops=%d, stuck in loop: %s",
 					dispatchCount, maxHops, loopReason)
-				log.Printf("[Ralph] Bead %s is stuck after %d dispatches, auto-blocking: %s",
-					b.ID, dispatchCount, loopReason)
+
+				// The escalation policy may send the bead to the CEO instead
+				if d.policy != nil && d.escalator != nil {
+					if escalate, why := d.policy.EscalateStuckBead(ctx, b, dispatchCount, loopReason); escalate {
+						if _, err := d.escalator.EscalateBeadToCEO(b.ID, reason+" ("+why+")", ""); err != nil {
+							dispatchLog.ErrorContext(ctx, "failed to escalate bead per policy", "bead_id", b.ID, "error", err)
+						} else {
+							dispatchLog.WarnContext(ctx, "bead stuck in a loop, escalated to the CEO per policy",
+								"bead_id", b.ID, "dispatch_count", dispatchCount, "policy", why)
+							skippedReasons["policy_escalated"]++
+							continue
+						}
+					}
+				}
+
+				// Ralph auto-block: stuck in loop — block autonomously instead of CEO escalation
+				dispatchLog.WarnContext(ctx, "bead stuck in a loop, auto-blocking",
+					"bead_id", b.ID, "dispatch_count", dispatchCount, "reason", loopReason)
 
 				progressSummary := d.loopDetector.GetProgressSummary(b)
 
@@ -479,8 +608,8 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 				revertStatus := "not_attempted"
 				firstSHA, _, commitCount := d.loopDetector.GetAgentCommitRange(b)
 				if firstSHA != "" && commitCount > 0 {
-					log.Printf("[Ralph] Attempting auto-revert of %d agent commits for bead %s (from %s)",
-						commitCount, b.ID, firstSHA)
+					dispatchLog.InfoContext(ctx, "recommending revert of agent commits",
+						"bead_id", b.ID, "commits", commitCount, "from_sha", firstSHA)
 					// Record intent — actual revert requires git.GitService which
 					// is project-scoped. The revert metadata tells the next handler
 					// (or human) exactly what to revert.
@@ -506,9 +635,9 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 					"context":     ctxUpdates,
 				}
 				if err := d.beads.UpdateBead(b.ID, updates); err != nil {
-					log.Printf("[Ralph] Failed to block bead %s: %v", b.ID, err)
+					dispatchLog.ErrorContext(ctx, "failed to block bead", "bead_id", b.ID, "error", err)
 				} else if triageAgent != "" {
-					log.Printf("[Ralph] Blocked bead %s reassigned to triage agent %s", b.ID, triageAgent)
+					dispatchLog.InfoContext(ctx, "blocked bead reassigned to triage agent", "bead_id", b.ID, "agent_id", triageAgent)
 				}
 
 				if d.eventBus != nil {
@@ -526,7 +655,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		}
 
 		if dispatchCount >= maxHops-1 {
-			log.Printf("[Dispatcher] WARNING: Bead %s has been dispatched %d times", b.ID, dispatchCount)
+			dispatchLog.WarnContext(ctx, "bead nearing the dispatch limit", "bead_id", b.ID, "dispatch_count", dispatchCount)
 		}
 
 		// Skip beads that recently failed — cooldown prevents re-dispatching
@@ -552,6 +681,27 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			}
 		}
 
+		// A bead whose plan awaits approval waits for it
+		if _, waiting := d.planStage(b); waiting {
+			skippedReasons["plan_awaiting_approval"]++
+			continue
+		}
+
+		// A bead whose estimate was over the remaining budget waits a while
+		if until, err := time.Parse(time.RFC3339, b.Context[BeadCostHeldUntilKey]); err == nil && time.Now().Before(until) {
+			skippedReasons["cost_estimate_held"]++
+			continue
+		}
+
+		// The budget policy may hold back beads of projects over budget
+		if d.policy != nil {
+			if ok, why := d.policy.DispatchAllowed(ctx, b); !ok {
+				dispatchLog.InfoContext(ctx, "bead held back by budget policy", "bead_id", b.ID, "policy", why)
+				skippedReasons["policy_budget_denied"]++
+				continue
+			}
+		}
+
 		// If bead is assigned to an agent, only dispatch to that agent.
 		if b.AssignedTo != "" {
 			assigned, ok := idleByID[b.AssignedTo]
@@ -564,17 +714,31 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			break
 		}
 
+		// A bead that selects a persona waits for one of its agents
+		if want := beadPersona(b); want != "" {
+			selected := d.personaMatcher.FindAgentByPersona(want, projectAgents(idleAgents, b.ProjectID))
+			if selected == nil {
+				skippedReasons["selected_persona_not_idle"]++
+				continue
+			}
+			ag = selected
+			candidate = b
+			dispatchLog.InfoContext(ctx, "matched bead to agent by selected persona",
+				"bead_id", b.ID, "agent_id", selected.ID, "persona", want)
+			break
+		}
+
 		// Check if bead has a workflow and needs specific role
 		var workflowRoleRequired string
 		if d.workflowEngine != nil {
 			execution, err := d.ensureBeadHasWorkflow(ctx, b)
 			if err != nil {
-				log.Printf("[Workflow] Error ensuring workflow for bead %s: %v", b.ID, err)
+				dispatchLog.ErrorContext(ctx, "failed to ensure bead workflow", "bead_id", b.ID, "error", err)
 			} else if execution != nil {
 				// Check for timeout before processing
 				if !d.workflowEngine.IsNodeReady(execution) {
 					skippedReasons["workflow_node_not_ready"]++
-					log.Printf("[Workflow] Bead %s workflow node not ready (may have timed out)", b.ID)
+					dispatchLog.DebugContext(ctx, "workflow node not ready (may have timed out)", "bead_id", b.ID)
 					continue
 				}
 
@@ -586,7 +750,8 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 						if agent != nil && normalizeRoleName(agent.Role) == requiredRoleKey {
 							ag = agent
 							candidate = b
-							log.Printf("[Workflow] Matched bead %s to agent %s by workflow role %s", b.ID, agent.Name, workflowRoleRequired)
+							dispatchLog.InfoContext(ctx, "matched bead to agent by workflow role",
+								"bead_id", b.ID, "agent_id", agent.ID, "role", workflowRoleRequired)
 							break
 						}
 					}
@@ -596,7 +761,8 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 					}
 
 					// No agent with exact role — fall through to persona/any-agent dispatch
-					log.Printf("[Dispatcher] Bead %s needs workflow role %q but no idle agent has it - falling through to any-agent dispatch", b.ID, workflowRoleRequired)
+					dispatchLog.InfoContext(ctx, "no idle agent has the workflow role, falling through to any-agent dispatch",
+						"bead_id", b.ID, "role", workflowRoleRequired)
 				}
 			}
 		}
@@ -608,11 +774,21 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			if matchedAgent != nil {
 				ag = matchedAgent
 				candidate = b
-				log.Printf("[Dispatcher] Matched bead %s to agent %s via persona hint '%s'", b.ID, matchedAgent.Name, personaHint)
+				dispatchLog.InfoContext(ctx, "matched bead to agent by persona hint",
+					"bead_id", b.ID, "agent_id", matchedAgent.ID, "persona_hint", personaHint)
 				break
 			}
 			// Persona hint found but no match - log it but fall through to assign any idle agent
-			log.Printf("[Dispatcher] Bead %s has persona hint '%s' but no exact match - will assign to any idle agent", b.ID, personaHint)
+			dispatchLog.InfoContext(ctx, "no agent matches the persona hint, assigning any idle agent",
+				"bead_id", b.ID, "persona_hint", personaHint)
+		}
+
+		// Prefer an agent that has successfully worked on the files the bead mentions
+		if expert := d.fileExpertise.FindExpertAgent(b, projectAgents(idleAgents, b.ProjectID)); expert != nil {
+			ag = expert
+			candidate = b
+			dispatchLog.InfoContext(ctx, "matched bead to agent by file expertise", "bead_id", b.ID, "agent_id", expert.ID)
+			break
 		}
 
 		// Pick an idle agent for this bead's project.
@@ -637,18 +813,18 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			skippedReasons["no_idle_agents_for_project"]++
 			continue
 		}
-		log.Printf("[Dispatcher] Assigning bead %s (project %s) to agent %s", b.ID, b.ProjectID, matchedAgent.Name)
+		dispatchLog.InfoContext(ctx, "assigning bead to agent", "bead_id", b.ID, "agent_id", matchedAgent.ID)
 		ag = matchedAgent
 		candidate = b
 		break
 	}
 
 	if len(skippedReasons) > 0 {
-		log.Printf("[Dispatcher] Skipped beads: %+v", skippedReasons)
+		dispatchLog.DebugContext(ctx, "skipped beads", "reasons", skippedReasons)
 	}
 
 	if candidate == nil {
-		log.Printf("[Dispatcher] No dispatchable beads found (ready: %d, idle agents: %d)", len(ready), len(idleAgents))
+		dispatchLog.DebugContext(ctx, "no dispatchable beads", "ready", len(ready), "idle_agents", len(idleAgents))
 		d.setStatus(StatusParked, "no dispatchable beads")
 		return &DispatchResult{Dispatched: false, ProjectID: projectID}, nil
 	}
@@ -662,8 +838,21 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		return &DispatchResult{Dispatched: false, ProjectID: selectedProjectID}, nil
 	}
 
-	// Estimate task complexity for smart provider routing
+	// Everything logged from here on, including by the worker and provider
+	// the task runs through, carries the bead and agent
+	ctx = logging.WithFields(ctx, "bead_id", candidate.ID, "agent_id", ag.ID, "project_id", selectedProjectID)
+
+	// Estimate task complexity for smart provider routing; a model tier the
+	// bead pins, e.g. on handoff, or else the one the agent's persona
+	// prefers takes precedence over the estimate
 	complexity := d.estimateBeadComplexity(candidate)
+	if tier := candidate.Context[BeadModelTierKey]; tier != "" {
+		complexity = TierComplexity(tier)
+		dispatchLog.DebugContext(ctx, "bead pins a model tier", "model_tier", tier)
+	} else if tier := d.personaModelTier(ag); tier != "" {
+		complexity = TierComplexity(tier)
+		dispatchLog.DebugContext(ctx, "persona prefers a model tier", "persona", ag.PersonaName, "model_tier", tier)
+	}
 
 	// Select provider based on complexity - match model size to task difficulty
 	if ag.ProviderID == "" || complexity != provider.ComplexityMedium {
@@ -673,15 +862,88 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			best := activeProviders[0]
 			prevProvider := ag.ProviderID
 			ag.ProviderID = best.Config.ID
-			log.Printf("[Dispatcher] Selected provider %s (params=%.0fB, score=%.0f) for %s complexity task %s (prev=%s)",
-				best.Config.ID, best.Config.ModelParamsB, best.Config.CapabilityScore,
-				complexity.String(), candidate.ID, prevProvider)
+			dispatchLog.InfoContext(ctx, "selected provider for task complexity",
+				"provider_id", best.Config.ID, "params_b", best.Config.ModelParamsB, "score", best.Config.CapabilityScore,
+				"complexity", complexity.String(), "previous_provider_id", prevProvider)
 		} else if ag.ProviderID == "" {
 			d.setStatus(StatusParked, "no active providers available")
 			return &DispatchResult{Dispatched: false, ProjectID: selectedProjectID, AgentID: ag.ID}, nil
 		}
 	}
 
+	proj, _ := d.projects.GetProject(selectedProjectID)
+
+	// Regulated projects may only be served by providers carrying the tags
+	// their compliance constraints require, the demo provider only serves
+	// demo projects, and providers reserved for an organization only serve
+	// its projects
+	if proj != nil && (proj.Compliance.Enabled() || d.demoProvider(ag.ProviderID) || d.foreignProvider(ag.ProviderID, proj.OrgID)) {
+		providerID, ok := d.compliantProvider(ag.ProviderID, complexity, proj.Compliance, proj.OrgID)
+		if !ok {
+			d.setStatus(StatusParked, "no active provider satisfies compliance constraints of project "+selectedProjectID)
+			return &DispatchResult{Dispatched: false, ProjectID: selectedProjectID, AgentID: ag.ID}, nil
+		}
+		if providerID != ag.ProviderID {
+			dispatchLog.InfoContext(ctx, "compliance routing to another provider",
+				"provider_id", providerID, "previous_provider_id", ag.ProviderID, "required_tags", proj.Compliance.RequiredProviderTags)
+			ag.ProviderID = providerID
+		}
+	}
+
+	// A saturated self-hosted server gets no new work while another provider
+	// can take it; when none can, dispatch waits for capacity
+	if d.providers.IsSaturated(ag.ProviderID) {
+		providerID, ok := d.unsaturatedProvider(complexity, proj)
+		if !ok {
+			d.setStatus(StatusParked, "every provider able to serve the bead is saturated")
+			return &DispatchResult{Dispatched: false, ProjectID: selectedProjectID, AgentID: ag.ID}, nil
+		}
+		dispatchLog.InfoContext(ctx, "routing around saturated provider",
+			"provider_id", providerID, "previous_provider_id", ag.ProviderID)
+		ag.ProviderID = providerID
+	}
+
+	taskID := fmt.Sprintf("task-%s-%d", candidate.ID, time.Now().UnixNano())
+	taskDescription := buildBeadDescription(candidate)
+	taskContext := buildBeadContext(candidate, proj)
+
+	// A bead in plan mode is first dispatched to plan, and then worked on
+	// by its approved plan
+	planOnly, _ := d.planStage(candidate)
+	if planOnly {
+		taskDescription = planTaskDescription(candidate, taskDescription)
+		dispatchLog.InfoContext(ctx, "dispatching bead to plan its work")
+	} else if plan := BeadPlan(candidate); plan != nil && plan.Status == models.PlanStatusApproved && PlanModeRequired(candidate, proj) {
+		taskDescription = approvedPlanDescription(plan, taskDescription)
+	}
+
+	// Estimate the dispatch before it starts; an estimate over the cost
+	// thresholds holds the bead back. A planning dispatch is not held
+	// back: its plan carries the estimate to whoever approves it.
+	var estimate *costestimate.Estimate
+	if d.costs != nil {
+		var err error
+		estimate, err = d.costs.EstimateDispatch(ctx, costestimate.Request{
+			BeadID:      candidate.ID,
+			ProjectID:   selectedProjectID,
+			TaskID:      taskID,
+			ProviderID:  ag.ProviderID,
+			Complexity:  complexity.String(),
+			PromptChars: len(taskDescription) + len(taskContext),
+		})
+		if err != nil {
+			dispatchLog.WarnContext(ctx, "failed to record cost estimate", "error", err)
+		}
+		if estimate != nil && !planOnly && !d.costEstimateAllows(ctx, candidate, estimate) {
+			d.setStatus(StatusParked, fmt.Sprintf("bead %s held back by its cost estimate", candidate.ID))
+			return &DispatchResult{Dispatched: false, ProjectID: selectedProjectID, BeadID: candidate.ID, AgentID: ag.ID}, nil
+		}
+	}
+
+	// Snapshot the bead and working copy before anything changes so the
+	// dispatch can be rolled back
+	snapshot := d.captureDispatchSnapshot(candidate, proj, ag.ID)
+
 	// Ensure bead is claimed/assigned.
 	if candidate.AssignedTo == "" {
 		if err := d.beads.ClaimBead(candidate.ID, ag.ID); err != nil {
@@ -710,20 +972,24 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 	dispatchCount++
 
 	// Update bead context with incremented dispatch count
+	countContext := map[string]string{
+		"dispatch_count": fmt.Sprintf("%d", dispatchCount),
+	}
+	if estimate != nil {
+		countContext[BeadCostEstimateKey] = fmt.Sprintf("%.4f", estimate.CostUSD)
+	}
 	countUpdates := map[string]interface{}{
-		"context": map[string]string{
-			"dispatch_count": fmt.Sprintf("%d", dispatchCount),
-		},
+		"context": countContext,
 	}
 	if err := d.beads.UpdateBead(candidate.ID, countUpdates); err != nil {
-		log.Printf("[Dispatcher] WARNING: Failed to update dispatch count for bead %s: %v", candidate.ID, err)
+		dispatchLog.WarnContext(ctx, "failed to update dispatch count", "error", err)
 		// Don't fail dispatch on this error - just log it
 	}
-	log.Printf("[Dispatcher] Bead %s dispatch count: %d", candidate.ID, dispatchCount)
+	dispatchLog.DebugContext(ctx, "dispatch count updated", "dispatch_count", dispatchCount)
 
 	// FIX #7: Log errors instead of silently discarding them
 	if err := d.agents.AssignBead(ag.ID, candidate.ID); err != nil {
-		log.Printf("[Dispatcher] CRITICAL: Failed to assign bead %s to agent %s: %v", candidate.ID, ag.ID, err)
+		dispatchLog.ErrorContext(ctx, "failed to assign bead to agent", "error", err)
 		// Continue anyway - the task will still be submitted to the worker
 	}
 	observability.Info("dispatch.assign", map[string]interface{}{
@@ -734,36 +1000,68 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 	})
 	if d.eventBus != nil {
 		if err := d.eventBus.PublishBeadEvent(eventbus.EventTypeBeadAssigned, candidate.ID, selectedProjectID, map[string]interface{}{"assigned_to": ag.ID}); err != nil {
-			log.Printf("[Dispatcher] Warning: Failed to publish bead assigned event for %s: %v", candidate.ID, err)
+			dispatchLog.WarnContext(ctx, "failed to publish bead assigned event", "error", err)
 		}
 		if err := d.eventBus.PublishBeadEvent(eventbus.EventTypeBeadStatusChange, candidate.ID, selectedProjectID, map[string]interface{}{"status": string(models.BeadStatusInProgress)}); err != nil {
-			log.Printf("[Dispatcher] Warning: Failed to publish bead status change event for %s: %v", candidate.ID, err)
+			dispatchLog.WarnContext(ctx, "failed to publish bead status change event", "error", err)
 		}
 	}
 
-	proj, _ := d.projects.GetProject(selectedProjectID)
-
 	// Get or create conversation session for multi-turn conversation support
 	var conversationSession *models.ConversationContext
 	if d.db != nil {
 		var err error
 		conversationSession, err = d.getOrCreateConversationSession(candidate, selectedProjectID)
 		if err != nil {
-			log.Printf("[Dispatcher] Warning: Failed to get/create conversation session for bead %s: %v", candidate.ID, err)
+			dispatchLog.WarnContext(ctx, "failed to get or create conversation session", "error", err)
 			// Continue without conversation session (falls back to single-shot mode)
 		} else if conversationSession != nil {
-			log.Printf("[Dispatcher] Using conversation session %s for bead %s (messages: %d)",
-				conversationSession.SessionID, candidate.ID, len(conversationSession.Messages))
+			dispatchLog.DebugContext(ctx, "using conversation session",
+				"session_id", conversationSession.SessionID, "messages", len(conversationSession.Messages))
+		}
+	}
+
+	// An interrupted loop left a checkpoint; the worker resumes from it
+	if d.db != nil {
+		if cp, err := d.db.GetDispatchCheckpoint(candidate.ID); err != nil {
+			dispatchLog.WarnContext(ctx, "failed to check dispatch checkpoint", "error", err)
+		} else if cp != nil {
+			dispatchLog.InfoContext(ctx, "resuming from dispatch checkpoint",
+				"iteration", cp.Iteration, "resume_count", cp.ResumeCount)
+			observability.Info("dispatch.resume", map[string]interface{}{
+				"agent_id":     ag.ID,
+				"bead_id":      candidate.ID,
+				"project_id":   selectedProjectID,
+				"iteration":    cp.Iteration,
+				"resume_count": cp.ResumeCount,
+			})
 		}
 	}
 
 	task := &worker.Task{
-		ID:                  fmt.Sprintf("task-%s-%d", candidate.ID, time.Now().UnixNano()),
-		Description:         buildBeadDescription(candidate),
-		Context:             buildBeadContext(candidate, proj),
+		ID:                  taskID,
+		Description:         taskDescription,
+		Context:             taskContext,
 		BeadID:              candidate.ID,
 		ProjectID:           selectedProjectID,
 		ConversationSession: conversationSession,
+		PlanOnly:            planOnly,
+	}
+
+	if snapshot != nil {
+		snapshot.ID = task.ID
+		snapshot.DispatchNumber = dispatchCount
+		d.saveDispatchSnapshot(snapshot)
+	}
+
+	// On a bead's first dispatch, hand the agent a map of the repository so
+	// it does not burn its first iterations (and trip the loop detector)
+	// listing directories
+	if dispatchCount == 1 && proj != nil {
+		if overview := d.repoSummarizer.Summarize(proj); overview != "" {
+			task.Files = overview
+			dispatchLog.DebugContext(ctx, "added repository overview to first dispatch")
+		}
 	}
 
 	d.setStatus(StatusActive, fmt.Sprintf("dispatching %s", candidate.ID))
@@ -774,7 +1072,11 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 	// next DispatchOnce won't re-assign it.
 	dispatchResult := &DispatchResult{Dispatched: true, ProjectID: selectedProjectID, BeadID: candidate.ID, AgentID: ag.ID, ProviderID: ag.ProviderID}
 
-	go func() {
+	// The run outlives this call, so it must not inherit the caller's
+	// cancellation: callers include API requests and Temporal activities,
+	// whose contexts end as soon as they return. An agent stuck in a run
+	// is freed by the stuck-agent reset instead.
+	go func(ctx context.Context) {
 		// Check if this is a commit node that needs serialization (Gap #2)
 		if d.workflowEngine != nil {
 			execution, err := d.workflowEngine.GetDatabase().GetWorkflowExecutionByBeadID(candidate.ID)
@@ -783,17 +1085,34 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 				if err == nil && node != nil && node.NodeType == workflow.NodeTypeCommit {
 					// Acquire commit lock before executing
 					if err := d.acquireCommitLock(ctx, candidate.ID, ag.ID); err != nil {
-						log.Printf("[Commit] Failed to acquire commit lock for bead %s: %v", candidate.ID, err)
+						dispatchLog.ErrorContext(ctx, "failed to acquire commit lock", "error", err)
 						// Continue without lock (fallback behavior)
 					} else {
 						defer d.releaseCommitLock()
-						log.Printf("[Commit] Acquired commit lock for bead %s (agent %s)", candidate.ID, ag.ID)
+						dispatchLog.InfoContext(ctx, "acquired commit lock")
 					}
 				}
 			}
 		}
 
+		execStart := time.Now()
 		result, execErr := d.agents.ExecuteTask(ctx, ag.ID, task)
+		if d.metrics != nil {
+			d.metrics.RecordDispatch(selectedProjectID, execErr == nil && result != nil && result.Success, time.Since(execStart))
+		}
+		if estimate != nil && result != nil && !planOnly {
+			d.costs.CompleteDispatch(ctx, task.ID, int64(result.TokensUsed), result.LoopIterations,
+				d.providers.EstimateCost(ag.ProviderID, "", result.TokensUsed, 0))
+		}
+		diff := d.completeDispatchSnapshot(snapshot, proj, result)
+		if d.judge != nil && execErr == nil && !planOnly && result != nil && result.LoopTerminalReason == "completed" && strings.TrimSpace(diff) != "" {
+			d.judge.JudgeDispatch(candidate, snapshot.ID, ag.ID, ag.ProviderID, result, diff)
+		}
+		if planOnly && execErr == nil && result != nil && result.Plan != nil {
+			d.proposePlan(ctx, candidate, ag, result, estimate)
+			d.setStatus(StatusParked, "idle")
+			return
+		}
 	if execErr != nil {
 		d.setStatus(StatusParked, "execution failed")
 		observability.Error("dispatch.execute", map[string]interface{}{
@@ -809,7 +1128,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		shouldRedispatch := "true"
 		if candidate.Context != nil && candidate.Context["terminal_reason"] == "max_iterations" {
 			shouldRedispatch = "false"
-			log.Printf("[Dispatcher] Bead %s previously hit max_iterations, not redispatching after error", candidate.ID)
+			dispatchLog.InfoContext(ctx, "bead previously hit max_iterations, not redispatching after error")
 		}
 
 		ctxUpdates := map[string]string{
@@ -823,6 +1142,9 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		}
 		if loopDetected {
 			ctxUpdates["loop_detected_reason"] = loopReason
+			if d.metrics != nil {
+				d.metrics.RecordLoopTrip(candidate.ProjectID, "history")
+			}
 			ctxUpdates["loop_detected_at"] = time.Now().UTC().Format(time.RFC3339)
 		}
 		updates := map[string]interface{}{"context": ctxUpdates}
@@ -831,10 +1153,10 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			updates["priority"] = models.BeadPriorityP0
 			updates["status"] = models.BeadStatusOpen
 			updates["assigned_to"] = triageAgent
-			log.Printf("[Dispatcher] Loop detected for bead %s, reassigning to triage agent %s", candidate.ID, triageAgent)
+			dispatchLog.WarnContext(ctx, "loop detected, reassigning bead to triage agent", "triage_agent_id", triageAgent)
 		}
 		if err := d.beads.UpdateBead(candidate.ID, updates); err != nil {
-			log.Printf("[Dispatcher] CRITICAL: Failed to update bead %s with context/loop detection: %v", candidate.ID, err)
+			dispatchLog.ErrorContext(ctx, "failed to update bead with context and loop detection", "error", err)
 		}
 		if d.eventBus != nil {
 			status := string(models.BeadStatusInProgress)
@@ -842,7 +1164,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 				status = string(models.BeadStatusOpen)
 			}
 			if err := d.eventBus.PublishBeadEvent(eventbus.EventTypeBeadStatusChange, candidate.ID, selectedProjectID, map[string]interface{}{"status": status}); err != nil {
-				log.Printf("[Dispatcher] Warning: Failed to publish bead status change event for %s: %v", candidate.ID, err)
+				dispatchLog.WarnContext(ctx, "failed to publish bead status change event", "error", err)
 			}
 		}
 
@@ -852,9 +1174,9 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			if err == nil && execution != nil {
 				// Report failure to workflow
 				if err := d.workflowEngine.FailNode(execution.ID, ag.ID, execErr.Error()); err != nil {
-					log.Printf("[Workflow] Failed to report failure to workflow for bead %s: %v", candidate.ID, err)
+					dispatchLog.ErrorContext(ctx, "failed to report failure to workflow", "error", err)
 				} else {
-					log.Printf("[Workflow] Reported failure to workflow for bead %s", candidate.ID)
+					dispatchLog.InfoContext(ctx, "reported failure to workflow")
 				}
 			}
 		}
@@ -890,7 +1212,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		if result.LoopTerminalReason == "max_iterations" {
 			ctxUpdates["redispatch_requested"] = "false"
 			ctxUpdates["max_iterations_reached_at"] = time.Now().UTC().Format(time.RFC3339)
-			log.Printf("[Dispatcher] Bead %s hit max_iterations, disabling redispatch to prevent infinite loop", candidate.ID)
+			dispatchLog.WarnContext(ctx, "bead hit max_iterations, disabling redispatch to prevent an infinite loop")
 		}
 
 		// On failure, set cooldown to prevent re-dispatching the same bead
@@ -906,6 +1228,9 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 	ctxUpdates["loop_detected"] = fmt.Sprintf("%t", loopDetected)
 	if loopDetected {
 		ctxUpdates["loop_detected_reason"] = loopReason
+		if d.metrics != nil {
+			d.metrics.RecordLoopTrip(candidate.ProjectID, "history")
+		}
 		ctxUpdates["loop_detected_at"] = time.Now().UTC().Format(time.RFC3339)
 	}
 
@@ -915,10 +1240,10 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		updates["priority"] = models.BeadPriorityP0
 		updates["status"] = models.BeadStatusOpen
 		updates["assigned_to"] = triageAgent
-		log.Printf("[Dispatcher] Task failure loop for bead %s, reassigning to triage agent %s", candidate.ID, triageAgent)
+		dispatchLog.WarnContext(ctx, "task failure loop, reassigning bead to triage agent", "triage_agent_id", triageAgent)
 	}
 	if err := d.beads.UpdateBead(candidate.ID, updates); err != nil {
-		log.Printf("[Dispatcher] CRITICAL: Failed to update bead %s after task failure: %v", candidate.ID, err)
+		dispatchLog.ErrorContext(ctx, "failed to update bead after task", "error", err)
 	}
 	if d.eventBus != nil {
 		status := string(models.BeadStatusInProgress)
@@ -926,12 +1251,13 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 			status = string(models.BeadStatusOpen)
 		}
 		if err := d.eventBus.PublishBeadEvent(eventbus.EventTypeBeadStatusChange, candidate.ID, selectedProjectID, map[string]interface{}{"status": status}); err != nil {
-			log.Printf("[Dispatcher] Warning: Failed to publish bead status change event for %s: %v", candidate.ID, err)
+			dispatchLog.WarnContext(ctx, "failed to publish bead status change event", "error", err)
 		}
 	}
 
-	// Advance workflow after successful task execution
-	if d.workflowEngine != nil && !loopDetected {
+	// Advance workflow after successful task execution; a handed-off bead
+	// is not done and stays on its node for the next persona
+	if d.workflowEngine != nil && !loopDetected && result.LoopTerminalReason != "handed_off" {
 		execution, err := d.workflowEngine.GetDatabase().GetWorkflowExecutionByBeadID(candidate.ID)
 		if err == nil && execution != nil {
 			// Advance workflow with success condition
@@ -941,22 +1267,22 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 				"tokens_used": fmt.Sprintf("%d", result.TokensUsed),
 			}
 			if err := d.workflowEngine.AdvanceWorkflow(execution.ID, workflow.EdgeConditionSuccess, ag.ID, resultData); err != nil {
-				log.Printf("[Workflow] Failed to advance workflow for bead %s: %v", candidate.ID, err)
+				dispatchLog.ErrorContext(ctx, "failed to advance workflow", "error", err)
 			} else {
 				// Get updated execution to check status
 				updatedExec, _ := d.workflowEngine.GetDatabase().GetWorkflowExecution(execution.ID)
 				if updatedExec != nil {
-					log.Printf("[Workflow] Advanced workflow for bead %s: status=%s, node=%s, cycle=%d",
-						candidate.ID, updatedExec.Status, updatedExec.CurrentNodeKey, updatedExec.CycleCount)
+					dispatchLog.InfoContext(ctx, "advanced workflow",
+						"status", updatedExec.Status, "node", updatedExec.CurrentNodeKey, "cycle", updatedExec.CycleCount)
 
 					// Check if workflow was escalated and needs CEO bead
 					if updatedExec.Status == workflow.ExecutionStatusEscalated && candidate.Context["escalation_bead_created"] != "true" {
-						log.Printf("[Workflow] Creating CEO escalation bead for workflow %s (bead %s)", updatedExec.ID, candidate.ID)
+						dispatchLog.InfoContext(ctx, "creating CEO escalation bead", "workflow_execution_id", updatedExec.ID)
 
 						// Get escalation info from workflow engine
 						title, description, err := d.workflowEngine.GetEscalationInfo(updatedExec)
 						if err != nil {
-							log.Printf("[Workflow] Failed to get escalation info for workflow %s: %v", updatedExec.ID, err)
+							dispatchLog.ErrorContext(ctx, "failed to get escalation info", "workflow_execution_id", updatedExec.ID, "error", err)
 						} else {
 							// Create CEO escalation bead
 							createdBead, err := d.beads.CreateBead(
@@ -967,9 +1293,10 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 								candidate.ProjectID,
 							)
 							if err != nil {
-								log.Printf("[Workflow] Failed to create CEO escalation bead: %v", err)
+								dispatchLog.ErrorContext(ctx, "failed to create CEO escalation bead", "error", err)
 							} else {
-								log.Printf("[Workflow] Created CEO escalation bead %s for workflow %s", createdBead.ID, updatedExec.ID)
+								dispatchLog.InfoContext(ctx, "created CEO escalation bead",
+									"escalation_bead_id", createdBead.ID, "workflow_execution_id", updatedExec.ID)
 
 								// Update the escalation bead with tags and context
 								escalationBeadUpdates := map[string]interface{}{
@@ -982,7 +1309,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 									},
 								}
 								if err := d.beads.UpdateBead(createdBead.ID, escalationBeadUpdates); err != nil {
-									log.Printf("[Workflow] Failed to update escalation bead with tags and context: %v", err)
+									dispatchLog.ErrorContext(ctx, "failed to update escalation bead with tags and context", "error", err)
 								}
 
 								// Mark original bead as having escalation bead created
@@ -993,7 +1320,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 									},
 								}
 								if err := d.beads.UpdateBead(candidate.ID, originalUpdates); err != nil {
-									log.Printf("[Workflow] Failed to update original bead with escalation info: %v", err)
+									dispatchLog.ErrorContext(ctx, "failed to update original bead with escalation info", "error", err)
 								}
 							}
 						}
@@ -1011,7 +1338,7 @@ func (d *Dispatcher) DispatchOnce(ctx context.Context, projectID string) (*Dispa
 		"provider_id": ag.ProviderID,
 		"status":      "success",
 	})
-	}() // end async goroutine
+	}(provider.WithPriority(context.WithoutCancel(ctx), provider.PriorityForBead(int(candidate.Priority)))) // end async goroutine
 
 	return dispatchResult, nil
 }
@@ -1077,12 +1404,12 @@ func (d *Dispatcher) getOrCreateConversationSession(bead *models.Bead, projectID
 		if err == nil && session != nil {
 			// Check if session is expired
 			if !session.IsExpired() {
-				log.Printf("[Dispatcher] Resuming conversation session %s for bead %s", sessionID, bead.ID)
+				dispatchLog.Debug("resuming conversation session", "session_id", sessionID, "bead_id", bead.ID)
 				return session, nil
 			}
-			log.Printf("[Dispatcher] Conversation session %s expired, creating new session", sessionID)
+			dispatchLog.Info("conversation session expired, creating a new one", "session_id", sessionID, "bead_id", bead.ID)
 		} else {
-			log.Printf("[Dispatcher] Failed to load conversation session %s: %v", sessionID, err)
+			dispatchLog.Warn("failed to load conversation session", "session_id", sessionID, "bead_id", bead.ID, "error", err)
 		}
 	}
 
@@ -1125,12 +1452,12 @@ func (d *Dispatcher) getOrCreateConversationSession(bead *models.Bead, projectID
 			"context": bead.Context,
 		}
 		if err := d.beads.UpdateBead(bead.ID, updates); err != nil {
-			log.Printf("[Dispatcher] Warning: Failed to update bead %s with session ID: %v", bead.ID, err)
+			dispatchLog.Warn("failed to store conversation session ID on bead", "bead_id", bead.ID, "error", err)
 			// Don't fail - session is created, just not stored in bead yet
 		}
 	}
 
-	log.Printf("[Dispatcher] Created new conversation session %s for bead %s", newSessionID, bead.ID)
+	dispatchLog.Info("created conversation session", "session_id", newSessionID, "bead_id", bead.ID)
 	return session, nil
 }
 
@@ -1161,15 +1488,8 @@ func buildBeadContext(b *models.Bead, p *models.Project) string {
 		}
 		sb.WriteString("\n")
 
-		// Find project work directory: WorkDir if set, otherwise standard clone path
-		workDir := p.WorkDir
-		if workDir == "" {
-			// Standard clone location inside container
-			workDir = filepath.Join("data", "projects", p.ID)
-		}
-
 		// Read AGENTS.md from project (like Claude Code reads it automatically)
-		agentsMD := readProjectFile(workDir, "AGENTS.md", 4000)
+		agentsMD := readProjectFile(projectWorkDir(p), "AGENTS.md", 4000)
 		if agentsMD != "" {
 			sb.WriteString("## Project Instructions (AGENTS.md)\n\n")
 			sb.WriteString(agentsMD)
@@ -1182,9 +1502,15 @@ func buildBeadContext(b *models.Bead, p *models.Project) string {
 
 	if len(b.Context) > 0 {
 		for k, v := range b.Context {
+			if k == BeadHandoffsKey {
+				continue
+			}
 			sb.WriteString(fmt.Sprintf("- %s: %s\n", k, v))
 		}
 	}
+	if handoffs := BeadHandoffs(b); len(handoffs) > 0 {
+		sb.WriteString(formatHandoff(handoffs[len(handoffs)-1]))
+	}
 
 	// Directive: act, don't plan
 	sb.WriteString(`
@@ -1232,7 +1558,7 @@ func (d *Dispatcher) ensureBeadHasWorkflow(ctx context.Context, bead *models.Bea
 	// Check if bead already has a workflow
 	execution, err := d.workflowEngine.GetDatabase().GetWorkflowExecutionByBeadID(bead.ID)
 	if err != nil {
-		log.Printf("[Workflow] Error checking workflow for bead %s: %v", bead.ID, err)
+		dispatchLog.ErrorContext(ctx, "failed to check bead workflow", "bead_id", bead.ID, "error", err)
 		return nil, err
 	}
 
@@ -1265,7 +1591,7 @@ func (d *Dispatcher) ensureBeadHasWorkflow(ctx context.Context, bead *models.Bea
 
 	if isSelfImprovement {
 		workflowType = "self-improvement"
-		log.Printf("[Workflow] Matched bead %s to self-improvement workflow (tags: %v)", bead.ID, bead.Tags)
+		dispatchLog.InfoContext(ctx, "matched bead to self-improvement workflow", "bead_id", bead.ID, "tags", bead.Tags)
 	} else if strings.Contains(title, "feature") || strings.Contains(title, "enhancement") {
 		workflowType = "feature"
 	} else if strings.Contains(title, "ui") || strings.Contains(title, "design") || strings.Contains(title, "css") || strings.Contains(title, "html") {
@@ -1277,18 +1603,18 @@ func (d *Dispatcher) ensureBeadHasWorkflow(ctx context.Context, bead *models.Bea
 	// Get workflow for this type
 	workflows, err := d.workflowEngine.GetDatabase().ListWorkflows(workflowType, bead.ProjectID)
 	if err != nil || len(workflows) == 0 {
-		log.Printf("[Workflow] No workflow found for type %s, bead %s", workflowType, bead.ID)
+		dispatchLog.DebugContext(ctx, "no workflow for bead type", "bead_id", bead.ID, "workflow_type", workflowType)
 		return nil, nil // No workflow available
 	}
 
 	// Start workflow for this bead
 	execution, err = d.workflowEngine.StartWorkflow(bead.ID, workflows[0].ID, bead.ProjectID)
 	if err != nil {
-		log.Printf("[Workflow] Failed to start workflow for bead %s: %v", bead.ID, err)
+		dispatchLog.ErrorContext(ctx, "failed to start workflow", "bead_id", bead.ID, "error", err)
 		return nil, err
 	}
 
-	log.Printf("[Workflow] Started workflow %s for bead %s", workflows[0].Name, bead.ID)
+	dispatchLog.InfoContext(ctx, "started workflow", "bead_id", bead.ID, "workflow", workflows[0].Name)
 	return execution, nil
 }
 
@@ -1338,6 +1664,36 @@ func (d *Dispatcher) getWorkflowRoleRequirement(execution *workflow.WorkflowExec
 	return node.RoleRequired
 }
 
+// personaModelTier returns the model tier the agent's persona prefers, or ""
+func (d *Dispatcher) personaModelTier(ag *models.Agent) string {
+	d.mu.RLock()
+	personas := d.personas
+	d.mu.RUnlock()
+	if personas == nil || ag.PersonaName == "" {
+		return ""
+	}
+	p, err := personas.LoadPersona(ag.PersonaName)
+	if err != nil {
+		return ""
+	}
+	return p.ModelTier
+}
+
+// TierComplexity returns the complexity whose required model tier is the
+// persona model tier
+func TierComplexity(tier string) provider.ComplexityLevel {
+	switch tier {
+	case persona.TierSmall:
+		return provider.ComplexitySimple
+	case persona.TierLarge:
+		return provider.ComplexityComplex
+	case persona.TierXLarge:
+		return provider.ComplexityExtended
+	default:
+		return provider.ComplexityMedium
+	}
+}
+
 // estimateBeadComplexity analyzes a bead to estimate task complexity for smart provider routing.
 // Simple tasks (review, check) go to small models; complex tasks (design, architect) go to large models.
 func (d *Dispatcher) estimateBeadComplexity(bead *models.Bead) provider.ComplexityLevel {
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/jordanhubbard/loom/internal/persona"
 	"github.com/jordanhubbard/loom/internal/provider"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
@@ -987,3 +988,34 @@ func TestDispatcher_ConcurrentSetStatus(t *testing.T) {
 		t.Errorf("Expected valid state, got %q", status.State)
 	}
 }
+
+// --- personaModelTier tests ---
+
+type stubPersonas map[string]*models.Persona
+
+func (s stubPersonas) LoadPersona(name string) (*models.Persona, error) {
+	if p, ok := s[name]; ok {
+		return p, nil
+	}
+	return nil, fmt.Errorf("persona not found: %s", name)
+}
+
+func TestPersonaModelTier(t *testing.T) {
+	d := &Dispatcher{}
+	ag := &models.Agent{PersonaName: "default/cto"}
+	if tier := d.personaModelTier(ag); tier != "" {
+		t.Errorf("expected no tier without personas, got %q", tier)
+	}
+
+	d.SetPersonas(stubPersonas{"default/cto": {ModelTier: persona.TierXLarge}})
+	tier := d.personaModelTier(ag)
+	if tier != persona.TierXLarge || TierComplexity(tier) != provider.ComplexityExtended {
+		t.Errorf("expected the xlarge tier to need extended complexity, got %q", tier)
+	}
+	if tier := d.personaModelTier(&models.Agent{PersonaName: "default/unknown"}); tier != "" {
+		t.Errorf("expected no tier for an unknown persona, got %q", tier)
+	}
+	if TierComplexity(persona.TierSmall) != provider.ComplexitySimple || TierComplexity(persona.TierMedium) != provider.ComplexityMedium {
+		t.Error("unexpected complexity for the small or medium tier")
+	}
+}
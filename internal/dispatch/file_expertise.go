@@ -0,0 +1,153 @@
+package dispatch
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// maxExpertiseFilesInPrompt caps the "previously worked on" list
+const maxExpertiseFilesInPrompt = 15
+
+// filePathPattern finds source file paths mentioned in bead text
+var filePathPattern = regexp.MustCompile(`(?:[\w.-]+/)*[\w-]+\.(?:go|py|js|jsx|ts|tsx|rs|java|kt|rb|c|h|cc|cpp|hpp|cs|swift|php|md|yaml|yml|json|toml|sql|sh|html|css|proto)\b`)
+
+// FileExpertiseProvider tracks which files each agent has successfully
+// modified. The map feeds dispatch routing and the agent's system prompt.
+// It implements the worker.FileExpertiseProvider interface.
+type FileExpertiseProvider struct {
+	db *database.Database
+}
+
+// NewFileExpertiseProvider creates a provider backed by the given database
+func NewFileExpertiseProvider(db *database.Database) *FileExpertiseProvider {
+	if db == nil {
+		return nil
+	}
+	return &FileExpertiseProvider{db: db}
+}
+
+// RecordFileEdits credits an agent with successful edits to the given files
+func (fp *FileExpertiseProvider) RecordFileEdits(projectID, agentID, personaName, beadID string, paths []string) error {
+	if fp == nil || fp.db == nil {
+		return nil
+	}
+	return fp.db.RecordFileExpertise(projectID, agentID, personaName, beadID, normalizeFilePaths(paths))
+}
+
+// GetExpertiseForPrompt formats the files an agent has previously modified
+// in a project as markdown for the system prompt
+func (fp *FileExpertiseProvider) GetExpertiseForPrompt(projectID, agentID string) string {
+	if fp == nil || fp.db == nil || projectID == "" || agentID == "" {
+		return ""
+	}
+
+	files, err := fp.db.GetAgentFileExpertise(projectID, agentID, maxExpertiseFilesInPrompt)
+	if err != nil {
+		dispatchLog.Error("failed to get file expertise", "agent_id", agentID, "project_id", projectID, "error", err)
+		return ""
+	}
+	if len(files) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("# Files You Have Worked On\n")
+	sb.WriteString("You have previously modified these files in this project successfully. Start from them when they are relevant:\n")
+	for _, f := range files {
+		sb.WriteString(fmt.Sprintf("- %s (%d successful edit", f.FilePath, f.EditCount))
+		if f.EditCount != 1 {
+			sb.WriteString("s")
+		}
+		sb.WriteString(")\n")
+	}
+	return sb.String()
+}
+
+// FindExpertAgent returns the idle agent with the most successful edits to
+// the files a bead mentions, or nil if no candidate has worked on them.
+// Expertise recorded under an agent's persona counts when the agent ID has
+// changed (agents are recreated on restart).
+func (fp *FileExpertiseProvider) FindExpertAgent(bead *models.Bead, agents []*models.Agent) *models.Agent {
+	if fp == nil || fp.db == nil || bead == nil || len(agents) == 0 {
+		return nil
+	}
+	paths := ExtractFilePaths(bead.Title + "\n" + bead.Description)
+	if len(paths) == 0 {
+		return nil
+	}
+
+	experts, err := fp.db.GetFileExperts(bead.ProjectID, paths)
+	if err != nil {
+		dispatchLog.Error("failed to look up file experts", "bead_id", bead.ID, "error", err)
+		return nil
+	}
+	if len(experts) == 0 {
+		return nil
+	}
+
+	byAgent := make(map[string]int)
+	byPersona := make(map[string]int)
+	for _, e := range experts {
+		byAgent[e.AgentID] += e.EditCount
+		if e.PersonaName != "" {
+			byPersona[e.PersonaName] += e.EditCount
+		}
+	}
+
+	var best *models.Agent
+	bestScore := 0
+	for _, a := range agents {
+		if a == nil {
+			continue
+		}
+		score := byAgent[a.ID]
+		if score == 0 && a.PersonaName != "" {
+			score = byPersona[a.PersonaName]
+		}
+		if score > bestScore {
+			best, bestScore = a, score
+		}
+	}
+	return best
+}
+
+// ExtractFilePaths returns the distinct source file paths mentioned in text
+func ExtractFilePaths(text string) []string {
+	return normalizeFilePaths(filePathPattern.FindAllString(text, -1))
+}
+
+// normalizeFilePaths cleans relative paths so the same file recorded from
+// different actions shares one key
+func normalizeFilePaths(paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	out := make([]string, 0, len(paths))
+	for _, p := range paths {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		p = strings.TrimPrefix(path.Clean(p), "./")
+		if seen[p] {
+			continue
+		}
+		seen[p] = true
+		out = append(out, p)
+	}
+	return out
+}
+
+// projectAgents filters agents to those eligible for a bead's project
+func projectAgents(agents []*models.Agent, projectID string) []*models.Agent {
+	out := make([]*models.Agent, 0, len(agents))
+	for _, a := range agents {
+		if a != nil && (a.ProjectID == projectID || a.ProjectID == "" || projectID == "") {
+			out = append(out, a)
+		}
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package dispatch
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func newFileExpertiseProvider(t *testing.T) *FileExpertiseProvider {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewFileExpertiseProvider(db)
+}
+
+func TestNewFileExpertiseProvider_NilDB(t *testing.T) {
+	if fp := NewFileExpertiseProvider(nil); fp != nil {
+		t.Error("Expected nil FileExpertiseProvider when database is nil")
+	}
+	var fp *FileExpertiseProvider
+	if fp.FindExpertAgent(&models.Bead{Title: "fix main.go"}, []*models.Agent{{ID: "a"}}) != nil {
+		t.Error("nil provider should not pick an agent")
+	}
+}
+
+func TestFileExpertise_PromptAndRouting(t *testing.T) {
+	fp := newFileExpertiseProvider(t)
+
+	if err := fp.RecordFileEdits("proj-1", "agent-a", "default/backend-engineer", "bead-1", []string{"./internal/auth/login.go", "internal/auth/login.go"}); err != nil {
+		t.Fatalf("RecordFileEdits failed: %v", err)
+	}
+	if err := fp.RecordFileEdits("proj-1", "agent-a", "default/backend-engineer", "bead-2", []string{"internal/auth/login.go"}); err != nil {
+		t.Fatalf("RecordFileEdits failed: %v", err)
+	}
+	if err := fp.RecordFileEdits("proj-1", "agent-b", "default/web-designer", "bead-3", []string{"web/app.js"}); err != nil {
+		t.Fatalf("RecordFileEdits failed: %v", err)
+	}
+
+	prompt := fp.GetExpertiseForPrompt("proj-1", "agent-a")
+	if !strings.Contains(prompt, "internal/auth/login.go (2 successful edits)") {
+		t.Errorf("prompt missing expertise entry:\n%s", prompt)
+	}
+	if fp.GetExpertiseForPrompt("proj-2", "agent-a") != "" {
+		t.Error("expertise should be scoped to the project")
+	}
+
+	agents := []*models.Agent{
+		{ID: "agent-b", PersonaName: "default/web-designer"},
+		{ID: "agent-a", PersonaName: "default/backend-engineer"},
+	}
+	bead := &models.Bead{ID: "bead-4", ProjectID: "proj-1", Title: "Login fails", Description: "Session handling in internal/auth/login.go drops cookies"}
+	if got := fp.FindExpertAgent(bead, agents); got == nil || got.ID != "agent-a" {
+		t.Errorf("expected agent-a, got %+v", got)
+	}
+
+	// A recreated agent inherits expertise through its persona
+	restarted := []*models.Agent{{ID: "agent-c", PersonaName: "default/backend-engineer"}}
+	if got := fp.FindExpertAgent(bead, restarted); got == nil || got.ID != "agent-c" {
+		t.Errorf("expected persona match agent-c, got %+v", got)
+	}
+
+	unrelated := &models.Bead{ID: "bead-5", ProjectID: "proj-1", Title: "Update README.md"}
+	if got := fp.FindExpertAgent(unrelated, agents); got != nil {
+		t.Errorf("expected no expert for unrelated files, got %s", got.ID)
+	}
+}
+
+func TestExtractFilePaths(t *testing.T) {
+	got := ExtractFilePaths("Fix ./cmd/loom/main.go and pkg/config/config.yaml, e.g. main.go again")
+	want := []string{"cmd/loom/main.go", "pkg/config/config.yaml", "main.go"}
+	if len(got) != len(want) {
+		t.Fatalf("ExtractFilePaths = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("ExtractFilePaths[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// BeadHandoffsKey is the bead context key holding the JSON list of
+	// handoffs the bead went through, oldest first
+	BeadHandoffsKey = "handoffs"
+
+	// BeadModelTierKey is the bead context key that pins the model tier the
+	// bead is worked with, overriding the tier its agent's persona prefers
+	BeadModelTierKey = "model_tier"
+)
+
+// Handoff is the document an agent leaves when it hands a bead to another
+// persona mid-task, e.g. a coder handing off to a reviewer
+type Handoff struct {
+	FromAgentID   string    `json:"from_agent_id"`
+	FromPersona   string    `json:"from_persona,omitempty"`
+	ToPersona     string    `json:"to_persona"`
+	ModelTier     string    `json:"model_tier,omitempty"`
+	Summary       string    `json:"summary"`
+	OpenQuestions []string  `json:"open_questions,omitempty"`
+	RelevantFiles []string  `json:"relevant_files,omitempty"`
+	HandedOffAt   time.Time `json:"handed_off_at"`
+}
+
+// BeadHandoffs returns the handoffs recorded on a bead, oldest first
+func BeadHandoffs(bead *models.Bead) []Handoff {
+	if bead == nil || bead.Context == nil || bead.Context[BeadHandoffsKey] == "" {
+		return nil
+	}
+	var handoffs []Handoff
+	if err := json.Unmarshal([]byte(bead.Context[BeadHandoffsKey]), &handoffs); err != nil {
+		return nil
+	}
+	return handoffs
+}
+
+// formatHandoff renders a handoff document for the prompt of the agent
+// picking the bead up
+func formatHandoff(h Handoff) string {
+	var sb strings.Builder
+	from := h.FromPersona
+	if from == "" {
+		from = h.FromAgentID
+	}
+	sb.WriteString(fmt.Sprintf("\n## Handoff from %s\n\n", from))
+	sb.WriteString("This bead was handed to you mid-task. Continue from where the previous agent left off.\n\n")
+	sb.WriteString(fmt.Sprintf("Summary:\n%s\n", h.Summary))
+	if len(h.OpenQuestions) > 0 {
+		sb.WriteString("\nOpen questions:\n")
+		for _, q := range h.OpenQuestions {
+			sb.WriteString(fmt.Sprintf("- %s\n", q))
+		}
+	}
+	if len(h.RelevantFiles) > 0 {
+		sb.WriteString("\nRelevant files:\n")
+		for _, f := range h.RelevantFiles {
+			sb.WriteString(fmt.Sprintf("- %s\n", f))
+		}
+	}
+	return sb.String()
+}
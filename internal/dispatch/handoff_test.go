@@ -0,0 +1,38 @@
+package dispatch
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestBuildBeadContext_RendersLatestHandoff(t *testing.T) {
+	handoffs, err := json.Marshal([]Handoff{
+		{FromAgentID: "agent-1", FromPersona: "default/engineering-manager", ToPersona: "default/web-designer", Summary: "Plan agreed"},
+		{FromAgentID: "agent-2", FromPersona: "default/web-designer", ToPersona: "default/code-reviewer", Summary: "Layout implemented",
+			OpenQuestions: []string{"Is the contrast sufficient?"}, RelevantFiles: []string{"web/index.html"}},
+	})
+	if err != nil {
+		t.Fatalf("marshal failed: %v", err)
+	}
+	bead := &models.Bead{ID: "bead-1", Type: "task", Context: map[string]string{
+		BeadHandoffsKey: string(handoffs),
+		BeadPersonaKey:  "default/code-reviewer",
+	}}
+
+	if got := BeadHandoffs(bead); len(got) != 2 || got[1].ToPersona != "default/code-reviewer" {
+		t.Fatalf("unexpected handoffs %+v", got)
+	}
+
+	result := buildBeadContext(bead, nil)
+	for _, want := range []string{"## Handoff from default/web-designer", "Layout implemented", "- Is the contrast sufficient?", "- web/index.html"} {
+		if !strings.Contains(result, want) {
+			t.Errorf("expected %q in bead context:\n%s", want, result)
+		}
+	}
+	if strings.Contains(result, "Plan agreed") || strings.Contains(result, "- handoffs:") {
+		t.Errorf("expected only the latest handoff, rendered rather than raw:\n%s", result)
+	}
+}
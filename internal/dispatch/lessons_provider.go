@@ -3,12 +3,12 @@ package dispatch
 import (
 	"context"
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/knowledge"
 	"github.com/jordanhubbard/loom/internal/memory"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
@@ -16,8 +16,14 @@ import (
 // LessonsProvider retrieves and records lessons from the database.
 // It implements the worker.LessonsProvider interface.
 type LessonsProvider struct {
-	db       *database.Database
-	embedder memory.Embedder
+	db         *database.Database
+	embedder   memory.Embedder
+	reembedder *memory.Reembedder
+	graph      *knowledge.Graph
+	detector   *memory.ContradictionDetector
+	checks     chan *models.Lesson // Lessons awaiting a contradiction check
+	sharedOrg  func(projectID string) string
+	index      *memory.LessonIndex
 }
 
 // NewLessonsProvider creates a new LessonsProvider backed by the given database.
@@ -39,27 +45,144 @@ func (lp *LessonsProvider) SetEmbedder(e memory.Embedder) {
 	}
 }
 
+// SetReembedder makes the re-embedding job's embedder the provider's, and
+// searches embed queries for every embedding version lessons are stored
+// under until the job has moved them all to the current one.
+func (lp *LessonsProvider) SetReembedder(r *memory.Reembedder) {
+	if lp != nil && r != nil {
+		lp.reembedder = r
+	}
+}
+
+// SetKnowledgeGraph links recorded lessons into the knowledge graph, and
+// puts the lessons it links to the files a task names ahead of the rest
+func (lp *LessonsProvider) SetKnowledgeGraph(g *knowledge.Graph) {
+	if lp != nil && g != nil {
+		lp.graph = g
+	}
+}
+
+// SetContradictionDetector checks each recorded lesson against the
+// project's similar lessons and supersedes those it contradicts, or the
+// new lesson itself when the older guidance is kept. Checks run once
+// Start is called.
+func (lp *LessonsProvider) SetContradictionDetector(d *memory.ContradictionDetector) {
+	if lp != nil && d != nil {
+		lp.detector = d
+		lp.checks = make(chan *models.Lesson, contradictionQueueSize)
+	}
+}
+
+// Start checks recorded lessons for contradictions in the background until
+// ctx is done
+func (lp *LessonsProvider) Start(ctx context.Context) {
+	if lp == nil || lp.detector == nil {
+		return
+	}
+	for i := 0; i < contradictionWorkers; i++ {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case lesson := <-lp.checks:
+					lp.checkContradictions(ctx, lesson)
+				}
+			}
+		}()
+	}
+}
+
+// SetSharedLessonScope puts the shared lessons of the organization orgOf
+// returns for a project into its prompts; an empty organization, for
+// projects that opted out, leaves them out
+func (lp *LessonsProvider) SetSharedLessonScope(orgOf func(projectID string) string) {
+	if lp != nil && orgOf != nil {
+		lp.sharedOrg = orgOf
+	}
+}
+
+// SetLessonIndex searches projects with enough lessons through an HNSW
+// index instead of comparing the task with each lesson
+func (lp *LessonsProvider) SetLessonIndex(x *memory.LessonIndex) {
+	if lp != nil && x != nil {
+		lp.index = x
+	}
+}
+
+// indexCandidates is how many candidates per lesson wanted an index search
+// returns for ranking; the index is approximate and ranks by similarity
+// alone, while lessons are ranked by relevance too
+const indexCandidates = 4
+
+// searchLessons returns the project's topK lessons most similar to the
+// queries, through the index when the project has one
+func (lp *LessonsProvider) searchLessons(ctx context.Context, projectID string, queries map[string][]float32, topK int) ([]*models.Lesson, error) {
+	if lp.index != nil {
+		ids, ok, err := lp.index.Search(projectID, queries, topK*indexCandidates)
+		if err != nil {
+			dispatchLog.WarnContext(ctx, "lesson index search failed, comparing every lesson", "project_id", projectID, "error", err)
+		} else if ok && len(ids) >= topK {
+			return lp.db.RankLessons(ids, queries, topK)
+		}
+	}
+	return lp.db.SearchLessonsByEmbeddings(projectID, queries, topK)
+}
+
+// queryEmbeddings embeds a search query, keyed by embedding version
+func (lp *LessonsProvider) queryEmbeddings(ctx context.Context, text string) (map[string][]float32, error) {
+	if lp.reembedder != nil {
+		return lp.reembedder.QueryEmbeddings(ctx, text)
+	}
+	embeddings, version, err := memory.EmbedWithVersion(ctx, lp.embedder, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
+		return nil, nil
+	}
+	return map[string][]float32{version: embeddings[0]}, nil
+}
+
+// embed embeds a lesson's text and returns its embedding version
+func (lp *LessonsProvider) embed(ctx context.Context, text string) ([][]float32, string, error) {
+	if lp.reembedder != nil {
+		return lp.reembedder.Embed(ctx, []string{text})
+	}
+	return memory.EmbedWithVersion(ctx, lp.embedder, []string{text})
+}
+
 // GetLessonsForPrompt retrieves lessons for a project and formats them as markdown
 // suitable for injection into the system prompt.
 func (lp *LessonsProvider) GetLessonsForPrompt(projectID string) string {
+	text, _ := lp.recentLessons(projectID)
+	return text
+}
+
+// recentLessons is GetLessonsForPrompt returning the IDs of the lessons
+// it formatted
+func (lp *LessonsProvider) recentLessons(projectID string) (string, []string) {
 	if lp == nil || lp.db == nil || projectID == "" {
-		return ""
+		return "", nil
 	}
 
 	lessons, err := lp.db.GetLessonsForProject(projectID, 15, 4000)
 	if err != nil {
-		log.Printf("[LessonsProvider] Failed to get lessons for project %s: %v", projectID, err)
-		return ""
+		dispatchLog.Error("failed to get lessons", "project_id", projectID, "error", err)
+		return "", nil
 	}
+	shared := lp.sharedLessons(context.Background(), projectID, nil, lessons)
 
-	if len(lessons) == 0 {
-		return ""
+	if len(lessons) == 0 && len(shared) == 0 {
+		return "", nil
 	}
 
 	var sb strings.Builder
-	sb.WriteString("The following lessons were learned from previous work on this project.\n")
-	sb.WriteString("Avoid repeating these mistakes:\n\n")
-
+	ids := make([]string, 0, len(lessons)+len(shared))
+	if len(lessons) > 0 {
+		sb.WriteString("The following lessons were learned from previous work on this project.\n")
+		sb.WriteString("Avoid repeating these mistakes:\n\n")
+	}
 	for _, l := range lessons {
 		sb.WriteString(fmt.Sprintf("### %s: %s\n", strings.ToUpper(l.Category), l.Title))
 		sb.WriteString(fmt.Sprintf("- %s\n", l.Detail))
@@ -67,20 +190,93 @@ func (lp *LessonsProvider) GetLessonsForPrompt(projectID string) string {
 			sb.WriteString("- (older lesson, may be less relevant)\n")
 		}
 		sb.WriteString("\n")
+		ids = append(ids, l.ID)
 	}
+	ids = append(ids, writeSharedLessons(&sb, shared, 0)...)
+
+	return sb.String(), ids
+}
+
+// maxSharedLessons is how many shared lessons go into one prompt
+const maxSharedLessons = 3
 
-	return sb.String()
+// sharedLessons returns the shared lessons of the project's organization
+// for a prompt: those most similar to the queries, or the newest without
+// them. Shared lessons promoted from lessons already selected are skipped.
+func (lp *LessonsProvider) sharedLessons(ctx context.Context, projectID string, queries map[string][]float32, selected []*models.Lesson) []*models.Lesson {
+	if lp.sharedOrg == nil {
+		return nil
+	}
+	orgID := lp.sharedOrg(projectID)
+	if orgID == "" {
+		return nil
+	}
+	var lessons []*models.Lesson
+	var err error
+	if len(queries) > 0 {
+		lessons, err = lp.db.SearchSharedLessonsByEmbeddings(orgID, queries, maxSharedLessons+len(selected))
+	} else {
+		lessons, err = lp.db.ListSharedLessons(orgID, models.ShareStatusShared, maxSharedLessons+len(selected))
+	}
+	if err != nil {
+		dispatchLog.WarnContext(ctx, "failed to get shared lessons", "project_id", projectID, "org_id", orgID, "error", err)
+		return nil
+	}
+	have := make(map[string]bool, len(selected))
+	for _, l := range selected {
+		have[l.ID] = true
+	}
+	var shared []*models.Lesson
+	for _, l := range lessons {
+		if len(shared) >= maxSharedLessons {
+			break
+		}
+		if !have[l.PromotedFrom] {
+			shared = append(shared, l)
+		}
+	}
+	return shared
+}
+
+// writeSharedLessons formats shared lessons as a section of the lessons
+// text, stopping before it grows past maxChars when that is positive, and
+// returns the IDs of the lessons written
+func writeSharedLessons(sb *strings.Builder, lessons []*models.Lesson, maxChars int) []string {
+	if len(lessons) == 0 {
+		return nil
+	}
+	header := "The following lessons were learned across your organization's projects:\n\n"
+	var ids []string
+	for _, l := range lessons {
+		entry := fmt.Sprintf("### %s: %s\n- %s\n\n", strings.ToUpper(l.Category), l.Title, l.Detail)
+		if len(ids) == 0 {
+			entry = header + entry
+		}
+		if maxChars > 0 && sb.Len()+len(entry) > maxChars {
+			break
+		}
+		sb.WriteString(entry)
+		ids = append(ids, l.ID)
+	}
+	return ids
 }
 
 // GetRelevantLessons retrieves the top-K lessons most semantically relevant
 // to the given task context. Falls back to GetLessonsForPrompt on any error.
 func (lp *LessonsProvider) GetRelevantLessons(projectID, taskContext string, topK int) string {
+	text, _ := lp.SelectLessons(projectID, taskContext, topK)
+	return text
+}
+
+// SelectLessons is GetRelevantLessons returning the IDs of the lessons
+// put in the text, so the dispatch's outcome can be credited to them
+func (lp *LessonsProvider) SelectLessons(projectID, taskContext string, topK int) (string, []string) {
 	if lp == nil || lp.db == nil || projectID == "" {
-		return ""
+		return "", nil
 	}
 
-	if taskContext == "" || lp.embedder == nil {
-		return lp.GetLessonsForPrompt(projectID)
+	if taskContext == "" || (lp.embedder == nil && lp.reembedder == nil) {
+		return lp.recentLessons(projectID)
 	}
 
 	if topK <= 0 {
@@ -89,44 +285,111 @@ func (lp *LessonsProvider) GetRelevantLessons(projectID, taskContext string, top
 
 	// Embed the task context
 	ctx := context.Background()
-	embeddings, err := lp.embedder.Embed(ctx, []string{taskContext})
+	queries, err := lp.queryEmbeddings(ctx, taskContext)
 	if err != nil {
-		log.Printf("[LessonsProvider] Embedding failed, falling back to recency: %v", err)
-		return lp.GetLessonsForPrompt(projectID)
+		dispatchLog.WarnContext(ctx, "lesson embedding failed, falling back to recency", "project_id", projectID, "error", err)
+		return lp.recentLessons(projectID)
 	}
-	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
-		return lp.GetLessonsForPrompt(projectID)
+	if len(queries) == 0 {
+		return lp.recentLessons(projectID)
 	}
 
-	queryEmb := embeddings[0]
+	// Lessons linked to the files the task names come first
+	scoped, touched := lp.scopedLessons(ctx, projectID, taskContext, topK)
 
 	// Search by similarity
-	lessons, err := lp.db.SearchLessonsBySimilarity(projectID, queryEmb, topK)
+	lessons, err := lp.searchLessons(ctx, projectID, queries, topK)
 	if err != nil {
-		log.Printf("[LessonsProvider] Similarity search failed, falling back to recency: %v", err)
-		return lp.GetLessonsForPrompt(projectID)
+		dispatchLog.WarnContext(ctx, "lesson similarity search failed, falling back to recency", "project_id", projectID, "error", err)
+		return lp.recentLessons(projectID)
 	}
+	lessons = mergeLessons(scoped, lessons, topK)
+	shared := lp.sharedLessons(ctx, projectID, queries, lessons)
 
-	if len(lessons) == 0 {
-		return ""
+	if len(lessons) == 0 && len(shared) == 0 {
+		return "", nil
 	}
 
 	// Format as markdown (max 2000 chars)
 	var sb strings.Builder
-	sb.WriteString("The following lessons are relevant to this task.\n")
-	sb.WriteString("Apply them where appropriate:\n\n")
+	if len(lessons) > 0 {
+		sb.WriteString("The following lessons are relevant to this task.\n")
+		sb.WriteString("Apply them where appropriate:\n\n")
+	}
 
 	totalChars := 0
+	var ids []string
 	for _, l := range lessons {
-		entry := fmt.Sprintf("### %s: %s\n- %s\n\n", strings.ToUpper(l.Category), l.Title, l.Detail)
+		entry := fmt.Sprintf("### %s: %s\n- %s\n", strings.ToUpper(l.Category), l.Title, l.Detail)
+		if files := touched[l.ID]; len(files) > 0 {
+			entry += fmt.Sprintf("- Learned from work on %s\n", strings.Join(files, ", "))
+		}
+		entry += "\n"
 		totalChars += len(entry)
 		if totalChars > 2000 {
 			break
 		}
 		sb.WriteString(entry)
+		ids = append(ids, l.ID)
 	}
+	ids = append(ids, writeSharedLessons(&sb, shared, 2000)...)
+
+	return sb.String(), ids
+}
 
-	return sb.String()
+// scopedLessons returns up to limit lessons the knowledge graph links to
+// the files named in the task context, with the files that linked each
+func (lp *LessonsProvider) scopedLessons(ctx context.Context, projectID, taskContext string, limit int) ([]*models.Lesson, map[string][]string) {
+	if lp.graph == nil {
+		return nil, nil
+	}
+	paths := ExtractFilePaths(taskContext)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	hits, err := lp.graph.LessonsTouching(projectID, paths...)
+	if err != nil {
+		dispatchLog.WarnContext(ctx, "knowledge graph lookup failed", "project_id", projectID, "error", err)
+		return nil, nil
+	}
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	ids := make([]string, len(hits))
+	touched := make(map[string][]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.LessonID
+		touched[h.LessonID] = h.Files
+	}
+	lessons, err := lp.db.GetLessonsByID(ids)
+	if err != nil {
+		dispatchLog.WarnContext(ctx, "failed to load lessons touching the task's files", "project_id", projectID, "error", err)
+		return nil, nil
+	}
+	current := lessons[:0]
+	for _, l := range lessons {
+		if l.SupersededBy == "" {
+			current = append(current, l)
+		}
+	}
+	return current, touched
+}
+
+// mergeLessons puts first ahead of the rest, dropping duplicates, up to
+// limit lessons
+func mergeLessons(first, rest []*models.Lesson, limit int) []*models.Lesson {
+	seen := make(map[string]bool, len(first)+len(rest))
+	var merged []*models.Lesson
+	for _, l := range append(first, rest...) {
+		if len(merged) >= limit {
+			break
+		}
+		if !seen[l.ID] {
+			seen[l.ID] = true
+			merged = append(merged, l)
+		}
+	}
+	return merged
 }
 
 // RecordLesson creates a new lesson from observed agent behavior.
@@ -148,27 +411,119 @@ func (lp *LessonsProvider) RecordLesson(projectID, category, title, detail, bead
 		RelevanceScore: 1.0,
 	}
 
+	return lp.record(lesson)
+}
+
+// RecordSharedLesson stores a lesson of an organization's shared tier the
+// way project lessons are recorded
+func (lp *LessonsProvider) RecordSharedLesson(lesson *models.Lesson) error {
+	if lp == nil || lp.db == nil {
+		return fmt.Errorf("lessons are not stored")
+	}
+	return lp.record(lesson)
+}
+
+// record stores a lesson, embedded for semantic search when it can be,
+// links it into the knowledge graph and queues its contradiction check
+func (lp *LessonsProvider) record(lesson *models.Lesson) error {
 	// Try to embed the lesson text for semantic search
-	if lp.embedder != nil {
-		text := title + " " + detail
+	if lp.embedder != nil || lp.reembedder != nil {
+		text := lesson.Title + " " + lesson.Detail
 		ctx := context.Background()
-		embeddings, err := lp.embedder.Embed(ctx, []string{text})
+		embeddings, version, err := lp.embed(ctx, text)
 		if err == nil && len(embeddings) > 0 && len(embeddings[0]) > 0 {
+			lesson.Embedding, lesson.EmbeddingVersion = embeddings[0], version
 			if err := lp.db.StoreLessonWithEmbedding(lesson, embeddings[0]); err != nil {
-				log.Printf("[LessonsProvider] Failed to record lesson with embedding: %v", err)
+				dispatchLog.Error("failed to record lesson with embedding", "project_id", lesson.ProjectID, "org_id", lesson.OrgID, "error", err)
 				return err
 			}
-			log.Printf("[LessonsProvider] Recorded lesson with embedding: [%s] %s", category, title)
+			dispatchLog.Info("recorded lesson with embedding", "project_id", lesson.ProjectID, "org_id", lesson.OrgID, "category", lesson.Category, "title", lesson.Title)
+			lp.linkLesson(lesson)
+			lp.queueContradictionCheck(lesson)
 			return nil
 		}
 		// Embedding failed — fall through to store without embedding
 	}
 
 	if err := lp.db.CreateLesson(lesson); err != nil {
-		log.Printf("[LessonsProvider] Failed to record lesson: %v", err)
+		dispatchLog.Error("failed to record lesson", "project_id", lesson.ProjectID, "org_id", lesson.OrgID, "error", err)
 		return err
 	}
 
-	log.Printf("[LessonsProvider] Recorded lesson: [%s] %s", category, title)
+	dispatchLog.Info("recorded lesson", "project_id", lesson.ProjectID, "org_id", lesson.OrgID, "category", lesson.Category, "title", lesson.Title)
+	lp.linkLesson(lesson)
+	return nil
+}
+
+// contradictionTimeout bounds the adjudication of one recorded lesson
+const contradictionTimeout = 2 * time.Minute
+
+// contradictionWorkers is how many lessons are adjudicated at once, and
+// contradictionQueueSize how many more may wait; lessons recorded past
+// that are not checked
+const (
+	contradictionWorkers   = 2
+	contradictionQueueSize = 100
+)
+
+// queueContradictionCheck queues a recorded lesson for a contradiction
+// check without blocking the agent that recorded it
+func (lp *LessonsProvider) queueContradictionCheck(lesson *models.Lesson) {
+	if lp.detector == nil {
+		return
+	}
+	select {
+	case lp.checks <- lesson:
+	default:
+		dispatchLog.Warn("contradiction checks are backed up; lesson not checked", "project_id", lesson.ProjectID, "lesson_id", lesson.ID)
+	}
+}
+
+// checkContradictions supersedes the lessons a recorded lesson contradicts.
+// Adjudication can take a model call per similar lesson, so it runs apart
+// from the agent that recorded the lesson.
+func (lp *LessonsProvider) checkContradictions(ctx context.Context, lesson *models.Lesson) {
+	ctx, cancel := context.WithTimeout(ctx, contradictionTimeout)
+	defer cancel()
+	if _, err := lp.detector.Check(ctx, lesson); err != nil {
+		dispatchLog.WarnContext(ctx, "lesson contradiction check failed", "project_id", lesson.ProjectID, "lesson_id", lesson.ID, "error", err)
+	}
+}
+
+// linkLesson adds a recorded lesson to the knowledge graph, so it is
+// linked before the next indexing run
+func (lp *LessonsProvider) linkLesson(lesson *models.Lesson) {
+	if lp.graph == nil {
+		return
+	}
+	if err := lp.graph.RecordLesson(lesson, ExtractFilePaths(lesson.Title+" "+lesson.Detail)); err != nil {
+		dispatchLog.Warn("failed to link lesson in the knowledge graph", "project_id", lesson.ProjectID, "error", err)
+	}
+}
+
+// RecordLessonOutcome records the lessons put into a dispatch's prompt
+// with how the dispatch ended, and rescores them by the outcomes of all
+// the dispatches they went into
+func (lp *LessonsProvider) RecordLessonOutcome(projectID, beadID, dispatchID string, lessonIDs []string, outcome string, succeeded bool) error {
+	if lp == nil || lp.db == nil || len(lessonIDs) == 0 {
+		return nil
+	}
+	now := time.Now()
+	injections := make([]*models.LessonInjection, len(lessonIDs))
+	for i, id := range lessonIDs {
+		injections[i] = &models.LessonInjection{
+			LessonID:   id,
+			DispatchID: dispatchID,
+			ProjectID:  projectID,
+			BeadID:     beadID,
+			Outcome:    outcome,
+			Succeeded:  succeeded,
+			CreatedAt:  now,
+		}
+	}
+	if err := lp.db.RecordLessonInjections(injections); err != nil {
+		dispatchLog.Error("failed to record lesson outcome", "project_id", projectID, "dispatch_id", dispatchID, "error", err)
+		return err
+	}
 	return nil
 }
@@ -1,11 +1,15 @@
 package dispatch
 
 import (
+	"context"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/knowledge"
 	"github.com/jordanhubbard/loom/internal/memory"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
@@ -330,6 +334,67 @@ func TestLessonsProvider_GetRelevantLessons_WithEmbeddings(t *testing.T) {
 	}
 }
 
+func TestLessonsProvider_GetRelevantLessons_ScopedToFiles(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	lp := NewLessonsProvider(db)
+	graph := knowledge.New(db, knowledge.Config{})
+	lp.SetKnowledgeGraph(graph)
+
+	// Unrelated lessons that match the task's words better
+	for i, title := range []string{"Retry flaky tests", "Retry timeouts in tests", "Retry network tests"} {
+		if err := lp.RecordLesson("proj-1", "test_failure", title, "Retry network tests on timeout", "bead-"+string(rune('a'+i)), "agent-1"); err != nil {
+			t.Fatalf("Failed to record lesson: %v", err)
+		}
+	}
+	if err := lp.RecordLesson("proj-1", "compiler_error", "Wrap provider errors", "Return wrapped errors from the client", "bead-7", "agent-2"); err != nil {
+		t.Fatalf("Failed to record lesson: %v", err)
+	}
+	// bead-7's commit changed the file the next task is about
+	if err := graph.RecordCommit("proj-1", git.CommitMetadata{
+		SHA: "abc123", BeadID: "bead-7", AgentID: "agent-2", Files: []string{"pkg/provider/client.go"},
+	}); err != nil {
+		t.Fatalf("Failed to record commit: %v", err)
+	}
+
+	result := lp.GetRelevantLessons("proj-1", "Retry network tests in pkg/provider/client.go", 2)
+	first := strings.Index(result, "Wrap provider errors")
+	if first < 0 {
+		t.Fatalf("Expected the lesson touching the file, got %q", result)
+	}
+	if other := strings.Index(result, "### TEST_FAILURE"); other >= 0 && other < first {
+		t.Errorf("Expected the lesson touching the file first, got %q", result)
+	}
+	if !strings.Contains(result, "Learned from work on pkg/provider/client.go") {
+		t.Errorf("Expected the file the lesson was found through, got %q", result)
+	}
+}
+
+func TestLessonsProvider_GetRelevantLessons_Indexed(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	lp := NewLessonsProvider(db)
+	lp.SetLessonIndex(memory.NewLessonIndex(db, memory.LessonIndexConfig{MinLessons: 3}))
+	for _, title := range []string{"Missing import", "Flaky tests", "Slow builds", "Wrong base branch"} {
+		if err := lp.RecordLesson("proj-1", "note", title, title+" detail", "bead-1", "agent-1"); err != nil {
+			t.Fatalf("Failed to record lesson: %v", err)
+		}
+	}
+
+	text, ids := lp.SelectLessons("proj-1", "missing import", 1)
+	if len(ids) != 1 || !strings.Contains(text, "Missing import") {
+		t.Errorf("Expected the indexed search to find the matching lesson, got %q", text)
+	}
+}
+
 func TestLessonsProvider_RecordLesson_NilCases(t *testing.T) {
 	// nil provider should be no-op
 	var nilLP *LessonsProvider
@@ -497,3 +562,184 @@ func TestLessonsProvider_GetLessonsForDifferentProjects(t *testing.T) {
 		t.Errorf("Expected empty result for nonexistent project, got %q", result3)
 	}
 }
+
+func TestLessonsProvider_SelectLessonsAndRecordOutcome(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	lp := NewLessonsProvider(db)
+
+	if err := lp.RecordLesson("proj-1", "compiler_error", "Missing import", "Always check imports", "bead-1", "agent-1"); err != nil {
+		t.Fatalf("Failed to record lesson: %v", err)
+	}
+	text, ids := lp.SelectLessons("proj-1", "", 5)
+	if !strings.Contains(text, "Missing import") || len(ids) != 1 {
+		t.Fatalf("expected the lesson and its ID, got %q, %v", text, ids)
+	}
+
+	if err := lp.RecordLessonOutcome("proj-1", "bead-2", "task-1", ids, "completed", true); err != nil {
+		t.Fatalf("RecordLessonOutcome failed: %v", err)
+	}
+	lessons, err := db.GetLessonsByID(ids)
+	if err != nil || len(lessons) != 1 || lessons[0].RelevanceScore <= 1.0 {
+		t.Errorf("expected the lesson's score raised by a successful dispatch, got %+v, %v", lessons, err)
+	}
+}
+
+func TestLessonsProvider_SharedLessons(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	lp := NewLessonsProvider(db)
+	optedOut := false
+	lp.SetSharedLessonScope(func(projectID string) string {
+		if optedOut {
+			return ""
+		}
+		return "acme"
+	})
+
+	if err := lp.RecordLesson("proj-1", "compiler_error", "Missing import", "Always check imports", "bead-1", "agent-1"); err != nil {
+		t.Fatalf("Failed to record lesson: %v", err)
+	}
+	own, ownIDs := lp.SelectLessons("proj-1", "", 5)
+	if strings.Contains(own, "organization") {
+		t.Fatalf("expected no shared section before sharing, got %q", own)
+	}
+
+	shared := &models.Lesson{ID: "shared-1", OrgID: "acme", Category: "model_behavior", Title: "YAML requests",
+		Detail: "Model x produces invalid JSON when asked for YAML", ShareStatus: models.ShareStatusShared}
+	if err := lp.RecordSharedLesson(shared); err != nil {
+		t.Fatalf("RecordSharedLesson failed: %v", err)
+	}
+	// A lesson promoted from one already in the prompt is not repeated
+	dup := &models.Lesson{ID: "shared-2", OrgID: "acme", Category: "compiler_error", Title: "Missing import",
+		Detail: "Always check imports", ShareStatus: models.ShareStatusShared, PromotedFrom: ownIDs[0]}
+	if err := lp.RecordSharedLesson(dup); err != nil {
+		t.Fatalf("RecordSharedLesson failed: %v", err)
+	}
+
+	for _, taskContext := range []string{"", "Write the YAML config"} {
+		text, ids := lp.SelectLessons("proj-1", taskContext, 5)
+		if !strings.Contains(text, "organization's projects") || !strings.Contains(text, "YAML requests") {
+			t.Errorf("expected the shared lesson for %q, got %q", taskContext, text)
+		}
+		if len(ids) != 2 || ids[1] != "shared-1" {
+			t.Errorf("expected the project lesson and shared-1 for %q, got %v", taskContext, ids)
+		}
+	}
+
+	// Other projects of the organization get shared lessons alone
+	if text, ids := lp.SelectLessons("proj-2", "", 5); len(ids) != 2 || strings.Contains(text, "this project") {
+		t.Errorf("expected only shared lessons for a new project, got %q, %v", text, ids)
+	}
+
+	optedOut = true
+	if text, _ := lp.SelectLessons("proj-1", "Write the YAML config", 5); strings.Contains(text, "YAML requests") {
+		t.Errorf("expected an opted out project to get no shared lessons, got %q", text)
+	}
+}
+
+// contradictingAdjudicator rules every pair a contradiction, keeping the
+// newer lesson
+type contradictingAdjudicator struct{}
+
+func (contradictingAdjudicator) Adjudicate(context.Context, *models.Lesson, *models.Lesson) (memory.Verdict, error) {
+	return memory.Verdict{Contradicts: true, Reason: "test"}, nil
+}
+
+func TestLessonsProvider_ContradictionChecksQueue(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	lp := NewLessonsProvider(db)
+	lp.SetContradictionDetector(memory.NewContradictionDetector(db, contradictingAdjudicator{}, memory.ContradictionConfig{}))
+
+	// Until Start, checks wait in a bounded queue and recording never blocks
+	for i := 0; i < contradictionQueueSize+5; i++ {
+		if err := lp.RecordLesson("proj-1", "testing", "Run the tests", "Always run the tests", "bead-1", "agent-1"); err != nil {
+			t.Fatalf("RecordLesson failed: %v", err)
+		}
+	}
+	if len(lp.checks) != contradictionQueueSize {
+		t.Fatalf("expected a full queue of %d, got %d", contradictionQueueSize, len(lp.checks))
+	}
+	for len(lp.checks) > 0 {
+		<-lp.checks
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lp.Start(ctx)
+	if err := lp.RecordLesson("proj-2", "testing", "Run the linter", "Always run the linter", "bead-2", "agent-1"); err != nil {
+		t.Fatalf("RecordLesson failed: %v", err)
+	}
+	if err := lp.RecordLesson("proj-2", "testing", "Run the linter", "Always run the linter", "bead-3", "agent-1"); err != nil {
+		t.Fatalf("RecordLesson failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		remaining, err := db.ListLessonsWithEmbeddings("proj-2", "", 10)
+		if err != nil {
+			t.Fatalf("ListLessonsWithEmbeddings failed: %v", err)
+		}
+		if len(remaining) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the older lesson to be superseded, %d remain", len(remaining))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestLessonsProvider_SharedLessonsCheckedWithinOrg(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+	lp := NewLessonsProvider(db)
+	lp.SetContradictionDetector(memory.NewContradictionDetector(db, contradictingAdjudicator{}, memory.ContradictionConfig{}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lp.Start(ctx)
+
+	for _, l := range []*models.Lesson{
+		{ID: "globex-1", OrgID: "globex", Title: "YAML requests", Detail: "Ask for JSON", ShareStatus: models.ShareStatusShared, CreatedAt: time.Now().Add(-2 * time.Minute)},
+		{ID: "acme-1", OrgID: "acme", Title: "YAML requests", Detail: "Ask for JSON", ShareStatus: models.ShareStatusShared, CreatedAt: time.Now().Add(-time.Minute)},
+		{ID: "acme-2", OrgID: "acme", Title: "YAML requests", Detail: "Ask for JSON", ShareStatus: models.ShareStatusShared, CreatedAt: time.Now()},
+	} {
+		if err := lp.RecordSharedLesson(l); err != nil {
+			t.Fatalf("RecordSharedLesson failed: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		lessons, err := db.GetLessonsByID([]string{"globex-1", "acme-1", "acme-2"})
+		if err != nil {
+			t.Fatalf("GetLessonsByID failed: %v", err)
+		}
+		superseded := map[string]string{}
+		for _, l := range lessons {
+			superseded[l.ID] = l.SupersededBy
+		}
+		if superseded["acme-1"] == "acme-2" {
+			if superseded["globex-1"] != "" || superseded["acme-2"] != "" {
+				t.Errorf("expected only acme-1 superseded, got %v", superseded)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected acme-1 to be superseded by acme-2, got %v", superseded)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
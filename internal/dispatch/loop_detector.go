@@ -5,7 +5,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"log"
 	"time"
 
 	"github.com/jordanhubbard/loom/pkg/models"
@@ -62,7 +61,7 @@ func (ld *LoopDetector) RecordAction(bead *models.Bead, action ActionRecord) err
 	// Get existing action history
 	history, err := ld.getActionHistory(bead)
 	if err != nil {
-		log.Printf("[LoopDetector] Failed to parse action history for bead %s: %v", bead.ID, err)
+		dispatchLog.Warn("failed to parse action history", "bead_id", bead.ID, "error", err)
 		history = []ActionRecord{}
 	}
 
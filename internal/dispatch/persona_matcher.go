@@ -162,3 +162,38 @@ func (pm *PersonaMatcher) FindAgentByPersonaHint(hint string, agents []*models.A
 	// No match found
 	return nil
 }
+
+// BeadPersonaKey is the bead context key that selects the persona whose
+// agents work the bead, e.g. default/qa-engineer or just qa-engineer
+const BeadPersonaKey = "persona"
+
+// beadPersona returns the persona a bead selects, or "" when it leaves it
+// open
+func beadPersona(bead *models.Bead) string {
+	if bead == nil || bead.Context == nil {
+		return ""
+	}
+	return QualifyPersonaName(bead.Context[BeadPersonaKey])
+}
+
+// QualifyPersonaName lowercases a persona name and takes an unnamespaced
+// one from the default personas
+func QualifyPersonaName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name != "" && !strings.Contains(name, "/") {
+		name = "default/" + name
+	}
+	return name
+}
+
+// FindAgentByPersona returns an agent of exactly the named persona, or nil.
+// Unlike hints, a selected persona is never matched loosely.
+func (pm *PersonaMatcher) FindAgentByPersona(name string, agents []*models.Agent) *models.Agent {
+	name = QualifyPersonaName(name)
+	for _, agent := range agents {
+		if agent != nil && QualifyPersonaName(agent.PersonaName) == name {
+			return agent
+		}
+	}
+	return nil
+}
@@ -406,3 +406,37 @@ func TestFindAgentByPersonaHint(t *testing.T) {
 		})
 	}
 }
+
+func TestFindAgentByPersona(t *testing.T) {
+	pm := NewPersonaMatcher()
+	agents := []*models.Agent{
+		{ID: "a1", PersonaName: "default/qa-engineer-lead"},
+		{ID: "a2", PersonaName: "default/qa-engineer"},
+		{ID: "a3", PersonaName: "team/reviewer"},
+	}
+
+	tests := []struct {
+		name     string
+		context  map[string]string
+		expected string
+	}{
+		{"no selection", nil, ""},
+		{"unnamespaced default persona", map[string]string{BeadPersonaKey: "QA-Engineer"}, "a2"},
+		{"namespaced persona", map[string]string{BeadPersonaKey: "team/reviewer"}, "a3"},
+		{"never matched loosely", map[string]string{BeadPersonaKey: "qa"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := beadPersona(&models.Bead{Context: tt.context})
+			got := ""
+			if want != "" {
+				if ag := pm.FindAgentByPersona(want, agents); ag != nil {
+					got = ag.ID
+				}
+			}
+			if got != tt.expected {
+				t.Errorf("selected %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/costestimate"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/internal/worker"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// BeadPlanModeKey, set to "true" in a bead's or its project's context,
+	// makes the bead's agent plan its work for approval before doing it;
+	// "false" on the bead turns plan mode off for it
+	BeadPlanModeKey = "plan_mode"
+	// BeadPlanKey is the bead context key holding the bead's execution
+	// plan as JSON
+	BeadPlanKey = "execution_plan"
+)
+
+// PlanApprover decides whether a proposed plan may run without a human
+type PlanApprover interface {
+	// AutoApprovePlan reports whether the plan approval policy approves
+	// the plan, and the rule and reason that decided
+	AutoApprovePlan(ctx context.Context, b *models.Bead, plan *models.ExecutionPlan) (approved bool, rule, reason string)
+}
+
+// SetPlanApprover lets a policy approve plans in place of a human
+func (d *Dispatcher) SetPlanApprover(approver PlanApprover) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.planApprover = approver
+}
+
+// BeadPlan returns the execution plan stored on a bead, or nil
+func BeadPlan(b *models.Bead) *models.ExecutionPlan {
+	if b == nil || b.Context[BeadPlanKey] == "" {
+		return nil
+	}
+	var plan models.ExecutionPlan
+	if err := json.Unmarshal([]byte(b.Context[BeadPlanKey]), &plan); err != nil {
+		return nil
+	}
+	return &plan
+}
+
+// EncodePlan renders a plan for a bead's context
+func EncodePlan(plan *models.ExecutionPlan) string {
+	data, _ := json.Marshal(plan)
+	return string(data)
+}
+
+// PlanModeRequired reports whether a bead must have an approved plan
+// before it is worked on: its own context decides, else its project's
+func PlanModeRequired(b *models.Bead, p *models.Project) bool {
+	switch strings.ToLower(b.Context[BeadPlanModeKey]) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return p != nil && strings.ToLower(p.Context[BeadPlanModeKey]) == "true"
+}
+
+// planStage returns whether a bead's next dispatch plans rather than
+// works, and whether it is held back waiting for its plan's approval
+func (d *Dispatcher) planStage(b *models.Bead) (planOnly, waiting bool) {
+	p, _ := d.projects.GetProject(b.ProjectID)
+	if !PlanModeRequired(b, p) {
+		return false, false
+	}
+	plan := BeadPlan(b)
+	switch {
+	case plan == nil || plan.Status == models.PlanStatusRejected:
+		return true, false
+	case plan.Status == models.PlanStatusProposed:
+		return false, true
+	}
+	return false, false
+}
+
+// planTaskDescription tells the agent of a planning dispatch what to do,
+// and why its last plan was rejected
+func planTaskDescription(b *models.Bead, description string) string {
+	var sb strings.Builder
+	sb.WriteString("## PLAN MODE\n\n")
+	sb.WriteString("This is a planning run: a human approves your plan before you do the work. ")
+	sb.WriteString("Read and search the code as you need to. Then issue the writes, edits and commands the work takes, in order, as you would to do it; ")
+	sb.WriteString("they are recorded in your plan and not performed. ")
+	sb.WriteString("Finish with done, giving a summary of the plan as the reason.\n\n")
+	if prev := BeadPlan(b); prev != nil && prev.Status == models.PlanStatusRejected {
+		sb.WriteString("Your previous plan was rejected")
+		if prev.ReviewReason != "" {
+			sb.WriteString(": " + prev.ReviewReason)
+		}
+		sb.WriteString(". Plan again with that in mind.\n\n")
+	}
+	sb.WriteString(description)
+	return sb.String()
+}
+
+// approvedPlanDescription hands the agent of a planned bead its approved
+// plan
+func approvedPlanDescription(plan *models.ExecutionPlan, description string) string {
+	var sb strings.Builder
+	sb.WriteString(description)
+	sb.WriteString("\n\n## Approved Plan\n\nA human approved this plan for the bead. Follow it; if it turns out to be wrong, say so in your done reason.\n\n")
+	if plan.Summary != "" {
+		sb.WriteString(plan.Summary + "\n\n")
+	}
+	for i, step := range plan.Steps {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, step)
+	}
+	return sb.String()
+}
+
+// proposePlan stores the plan a planning dispatch produced on its bead,
+// approved at once when the plan approval policy allows it
+func (d *Dispatcher) proposePlan(ctx context.Context, b *models.Bead, ag *models.Agent, result *worker.TaskResult, estimate *costestimate.Estimate) {
+	plan := result.Plan
+	plan.Status = models.PlanStatusProposed
+	plan.CreatedAt = time.Now().UTC()
+	if estimate != nil {
+		plan.EstimatedCostUSD = estimate.CostUSD
+		plan.EstimatedTokens = estimate.Tokens
+	}
+
+	d.mu.RLock()
+	approver := d.planApprover
+	d.mu.RUnlock()
+	eventType := eventbus.EventTypeBeadPlanProposed
+	if approver != nil {
+		if ok, rule, reason := approver.AutoApprovePlan(ctx, b, plan); ok {
+			now := time.Now().UTC()
+			plan.Status = models.PlanStatusApproved
+			plan.ReviewedBy = "policy"
+			if rule != "" {
+				plan.ReviewedBy = "policy:" + rule
+			}
+			plan.ReviewReason = reason
+			plan.ReviewedAt = &now
+			eventType = eventbus.EventTypeBeadPlanApproved
+		}
+	}
+
+	updates := map[string]interface{}{"context": map[string]string{
+		BeadPlanKey:            EncodePlan(plan),
+		"last_run_at":          time.Now().UTC().Format(time.RFC3339),
+		"agent_id":             ag.ID,
+		"provider_id":          ag.ProviderID,
+		"agent_tokens":         fmt.Sprintf("%d", result.TokensUsed),
+		"redispatch_requested": "true",
+	}}
+	if err := d.beads.UpdateBead(b.ID, updates); err != nil {
+		dispatchLog.ErrorContext(ctx, "failed to store execution plan", "error", err)
+		return
+	}
+	dispatchLog.InfoContext(ctx, "execution plan proposed", "status", plan.Status,
+		"files", len(plan.Files), "commands", len(plan.Commands), "estimate_usd", plan.EstimatedCostUSD)
+	if d.eventBus != nil {
+		_ = d.eventBus.PublishBeadEvent(eventType, b.ID, b.ProjectID, map[string]interface{}{
+			"title":        b.Title,
+			"agent_id":     ag.ID,
+			"actor_id":     ag.ID,
+			"actor_type":   "agent",
+			"files":        len(plan.Files),
+			"commands":     len(plan.Commands),
+			"estimate_usd": plan.EstimatedCostUSD,
+			"reviewed_by":  plan.ReviewedBy,
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package dispatch
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestPlanModeRequired(t *testing.T) {
+	project := &models.Project{Context: map[string]string{BeadPlanModeKey: "true"}}
+	tests := []struct {
+		name    string
+		bead    map[string]string
+		project *models.Project
+		want    bool
+	}{
+		{"off by default", nil, nil, false},
+		{"project opts in", nil, project, true},
+		{"bead opts out", map[string]string{BeadPlanModeKey: "false"}, project, false},
+		{"bead opts in", map[string]string{BeadPlanModeKey: "TRUE"}, nil, true},
+	}
+	for _, tt := range tests {
+		if got := PlanModeRequired(&models.Bead{Context: tt.bead}, tt.project); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlanTaskDescription_CarriesRejection(t *testing.T) {
+	plan := &models.ExecutionPlan{Status: models.PlanStatusRejected, ReviewReason: "do not touch the schema"}
+	bead := &models.Bead{Context: map[string]string{BeadPlanKey: EncodePlan(plan)}}
+
+	got := planTaskDescription(bead, "Fix the login bug")
+	for _, want := range []string{"## PLAN MODE", "do not touch the schema", "Fix the login bug"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in description:\n%s", want, got)
+		}
+	}
+
+	approved := &models.ExecutionPlan{Summary: "Patch auth.go", Steps: []string{"edit_code: auth.go"}}
+	got = approvedPlanDescription(approved, "Fix the login bug")
+	if !strings.Contains(got, "## Approved Plan") || !strings.Contains(got, "1. edit_code: auth.go") {
+		t.Errorf("expected the approved plan in description:\n%s", got)
+	}
+}
@@ -0,0 +1,269 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// repoSummaryTreeDepth is how many directory levels the overview lists
+	repoSummaryTreeDepth = 2
+	// repoSummaryScanDepth is how deep entry points are searched for
+	repoSummaryScanDepth = 4
+	// repoSummaryMaxEntries caps the directory listing
+	repoSummaryMaxEntries = 60
+	// repoSummaryMaxLen caps the whole overview injected into the prompt
+	repoSummaryMaxLen = 4000
+)
+
+// repoSummarySkipDirs are never listed or searched for entry points
+var repoSummarySkipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, "__pycache__": true, ".venv": true, "venv": true, ".beads": true,
+	".idea": true, ".vscode": true, "coverage": true,
+}
+
+// entryPointNames are files that usually start a program or define a build
+var entryPointNames = map[string]bool{
+	"main.go": true, "main.py": true, "__main__.py": true, "app.py": true, "manage.py": true,
+	"index.js": true, "index.ts": true, "main.ts": true, "main.rs": true, "lib.rs": true,
+	"Makefile": true, "Dockerfile": true, "docker-compose.yml": true, "go.mod": true,
+	"package.json": true, "Cargo.toml": true, "pyproject.toml": true, "setup.py": true,
+	"pom.xml": true, "build.gradle": true, "CMakeLists.txt": true,
+}
+
+// makeTargetPattern matches a Makefile target definition
+var makeTargetPattern = regexp.MustCompile(`(?m)^([A-Za-z][\w-]*):`)
+
+// RepoSummarizer builds a one-time overview of a project's repository
+// (layout, entry points, build and test commands) for a bead's first
+// dispatch, so agents do not spend their early iterations exploring.
+// Overviews are cached per commit, since they only change when the code does.
+type RepoSummarizer struct {
+	mu    sync.Mutex
+	cache map[string]repoSummary // projectID -> overview of its latest commit
+}
+
+type repoSummary struct {
+	sha     string
+	summary string
+}
+
+// NewRepoSummarizer creates an empty summarizer
+func NewRepoSummarizer() *RepoSummarizer {
+	return &RepoSummarizer{cache: make(map[string]repoSummary)}
+}
+
+// Summarize returns the overview for a project's working tree. Repositories
+// without a resolvable HEAD are summarized on every call.
+func (rs *RepoSummarizer) Summarize(p *models.Project) string {
+	if rs == nil || p == nil {
+		return ""
+	}
+	workDir := projectWorkDir(p)
+	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+		return ""
+	}
+
+	sha := headCommit(workDir)
+	if sha != "" {
+		rs.mu.Lock()
+		cached, ok := rs.cache[p.ID]
+		rs.mu.Unlock()
+		if ok && cached.sha == sha {
+			return cached.summary
+		}
+	}
+
+	summary := buildRepoSummary(workDir, sha)
+	if sha != "" {
+		rs.mu.Lock()
+		rs.cache[p.ID] = repoSummary{sha: sha, summary: summary}
+		rs.mu.Unlock()
+	}
+	return summary
+}
+
+// projectWorkDir returns where a project's repository is checked out
+func projectWorkDir(p *models.Project) string {
+	if p.WorkDir != "" {
+		return p.WorkDir
+	}
+	// Standard clone location inside container
+	return filepath.Join("data", "projects", p.ID)
+}
+
+// headCommit returns the commit checked out in dir, or "" if there is none
+func headCommit(dir string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// buildRepoSummary renders the overview as markdown
+func buildRepoSummary(workDir, sha string) string {
+	tree, entryPoints := scanRepo(workDir)
+	commands := detectBuildCommands(workDir)
+	if len(tree) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString("## Repository Overview\n\n")
+	if sha != "" {
+		sb.WriteString(fmt.Sprintf("Summary of the repository at commit %s. Use it instead of exploring the layout from scratch.\n\n", shortSHA(sha)))
+	} else {
+		sb.WriteString("Summary of the repository. Use it instead of exploring the layout from scratch.\n\n")
+	}
+
+	sb.WriteString("### Layout\n```\n")
+	for _, line := range tree {
+		sb.WriteString(line + "\n")
+	}
+	sb.WriteString("```\n")
+
+	if len(entryPoints) > 0 {
+		sb.WriteString("\n### Key Files\n")
+		for _, f := range entryPoints {
+			sb.WriteString("- " + f + "\n")
+		}
+	}
+
+	if len(commands) > 0 {
+		sb.WriteString("\n### Build and Test\n")
+		for _, c := range commands {
+			sb.WriteString("- `" + c + "`\n")
+		}
+	}
+
+	summary := sb.String()
+	if len(summary) > repoSummaryMaxLen {
+		summary = summary[:repoSummaryMaxLen] + "\n... (truncated)\n"
+	}
+	return summary
+}
+
+// scanRepo lists the top levels of the tree and finds entry point files
+// in its upper levels
+func scanRepo(workDir string) (tree []string, entryPoints []string) {
+	truncated := false
+	_ = filepath.WalkDir(workDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || path == workDir {
+			return nil
+		}
+		rel, relErr := filepath.Rel(workDir, path)
+		if relErr != nil {
+			return nil
+		}
+		depth := strings.Count(rel, string(filepath.Separator)) + 1
+
+		if d.IsDir() {
+			if repoSummarySkipDirs[d.Name()] || (strings.HasPrefix(d.Name(), ".") && d.Name() != ".github") || depth >= repoSummaryScanDepth {
+				return filepath.SkipDir
+			}
+			if depth <= repoSummaryTreeDepth {
+				tree, truncated = appendTreeLine(tree, truncated, rel, depth, true)
+			}
+			return nil
+		}
+
+		if depth <= repoSummaryTreeDepth {
+			tree, truncated = appendTreeLine(tree, truncated, rel, depth, false)
+		}
+		if entryPointNames[d.Name()] {
+			entryPoints = append(entryPoints, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	sort.SliceStable(entryPoints, func(i, j int) bool {
+		return strings.Count(entryPoints[i], "/") < strings.Count(entryPoints[j], "/")
+	})
+	if len(entryPoints) > 20 {
+		entryPoints = entryPoints[:20]
+	}
+	return tree, entryPoints
+}
+
+func appendTreeLine(tree []string, truncated bool, rel string, depth int, isDir bool) ([]string, bool) {
+	if len(tree) >= repoSummaryMaxEntries {
+		if !truncated {
+			tree = append(tree, "...")
+		}
+		return tree, true
+	}
+	name := filepath.Base(rel)
+	if isDir {
+		name += "/"
+	}
+	return append(tree, strings.Repeat("  ", depth-1)+name), truncated
+}
+
+// detectBuildCommands infers build and test commands from the build files
+// at the root of the repository
+func detectBuildCommands(workDir string) []string {
+	var commands []string
+	exists := func(name string) bool {
+		_, err := os.Stat(filepath.Join(workDir, name))
+		return err == nil
+	}
+
+	if data, err := os.ReadFile(filepath.Join(workDir, "Makefile")); err == nil {
+		for _, m := range makeTargetPattern.FindAllStringSubmatch(string(data), -1) {
+			switch m[1] {
+			case "build", "test", "lint", "check", "all":
+				commands = append(commands, "make "+m[1])
+			}
+		}
+	}
+	if exists("go.mod") {
+		commands = append(commands, "go build ./...", "go test ./...")
+	}
+	if data, err := os.ReadFile(filepath.Join(workDir, "package.json")); err == nil {
+		var pkg struct {
+			Scripts map[string]string `json:"scripts"`
+		}
+		if json.Unmarshal(data, &pkg) == nil {
+			runner := "npm"
+			if exists("yarn.lock") {
+				runner = "yarn"
+			} else if exists("pnpm-lock.yaml") {
+				runner = "pnpm"
+			}
+			for _, s := range []string{"build", "test", "lint"} {
+				if _, ok := pkg.Scripts[s]; ok {
+					commands = append(commands, runner+" run "+s)
+				}
+			}
+		}
+	}
+	if exists("Cargo.toml") {
+		commands = append(commands, "cargo build", "cargo test")
+	}
+	if exists("pyproject.toml") || exists("setup.py") || exists("pytest.ini") {
+		commands = append(commands, "pytest")
+	}
+	return commands
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
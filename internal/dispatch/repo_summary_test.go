@@ -0,0 +1,95 @@
+package dispatch
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func writeRepoFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gitCommitAll(t *testing.T, dir string) {
+	t.Helper()
+	for _, args := range [][]string{
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@test", "commit", "-qm", "snapshot"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+}
+
+func TestRepoSummarizer_SummarizesAndCachesPerCommit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	if out, err := exec.Command("git", "init", "-q", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	writeRepoFile(t, dir, "go.mod", "module example.com/app\n")
+	writeRepoFile(t, dir, "Makefile", "build:\n\tgo build ./...\ntest:\n\tgo test ./...\n")
+	writeRepoFile(t, dir, "cmd/app/main.go", "package main\n")
+	writeRepoFile(t, dir, "internal/store/store.go", "package store\n")
+	writeRepoFile(t, dir, "node_modules/left-pad/index.js", "")
+	gitCommitAll(t, dir)
+
+	rs := NewRepoSummarizer()
+	proj := &models.Project{ID: "proj-1", WorkDir: dir}
+	summary := rs.Summarize(proj)
+
+	for _, want := range []string{"## Repository Overview", "cmd/", "internal/", "cmd/app/main.go", "`make build`", "`make test`", "`go test ./...`"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary missing %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "node_modules") {
+		t.Errorf("summary should skip dependency directories:\n%s", summary)
+	}
+
+	// Uncommitted changes do not invalidate the cached overview
+	writeRepoFile(t, dir, "web/package.json", `{"scripts":{"test":"jest"}}`)
+	if got := rs.Summarize(proj); got != summary {
+		t.Error("expected cached summary for the same commit")
+	}
+
+	gitCommitAll(t, dir)
+	if got := rs.Summarize(proj); !strings.Contains(got, "web/package.json") {
+		t.Errorf("expected a fresh summary after a new commit:\n%s", got)
+	}
+}
+
+func TestRepoSummarizer_MissingWorkDir(t *testing.T) {
+	rs := NewRepoSummarizer()
+	if got := rs.Summarize(&models.Project{ID: "p", WorkDir: filepath.Join(t.TempDir(), "missing")}); got != "" {
+		t.Errorf("expected no summary for a missing checkout, got %q", got)
+	}
+	if got := (*RepoSummarizer)(nil).Summarize(&models.Project{ID: "p"}); got != "" {
+		t.Errorf("nil summarizer should return empty, got %q", got)
+	}
+}
+
+func TestDetectBuildCommands_PackageJSON(t *testing.T) {
+	dir := t.TempDir()
+	writeRepoFile(t, dir, "package.json", `{"scripts":{"build":"tsc","test":"jest","start":"node ."}}`)
+	writeRepoFile(t, dir, "yarn.lock", "")
+
+	got := strings.Join(detectBuildCommands(dir), ",")
+	if got != "yarn run build,yarn run test" {
+		t.Errorf("detectBuildCommands() = %q", got)
+	}
+}
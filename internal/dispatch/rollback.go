@@ -0,0 +1,395 @@
+package dispatch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/artifacts"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/internal/worker"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// RevertDispatchResult reports what rolling back a dispatch undid
+type RevertDispatchResult struct {
+	DispatchID       string       `json:"dispatch_id"`
+	BeadID           string       `json:"bead_id"`
+	ClosedPR         int          `json:"closed_pr,omitempty"`
+	DeletedBranches  []string     `json:"deleted_branches"`
+	RevertedCommits  []string     `json:"reverted_commits"`
+	RestoredWorktree bool         `json:"restored_worktree"`
+	Bead             *models.Bead `json:"bead"`
+}
+
+// captureDispatchSnapshot records the bead's state before it is claimed,
+// and the project's working copy, so the dispatch can be rolled back
+func (d *Dispatcher) captureDispatchSnapshot(bead *models.Bead, proj *models.Project, agentID string) *database.DispatchSnapshot {
+	if d.db == nil || bead == nil {
+		return nil
+	}
+
+	beadContext, err := json.Marshal(bead.Context)
+	if err != nil {
+		dispatchLog.Warn("failed to snapshot bead context", "bead_id", bead.ID, "error", err)
+		return nil
+	}
+	snap := &database.DispatchSnapshot{
+		BeadID:          bead.ID,
+		ProjectID:       bead.ProjectID,
+		AgentID:         agentID,
+		BeadStatus:      string(bead.Status),
+		BeadAssignedTo:  bead.AssignedTo,
+		BeadContextJSON: string(beadContext),
+		Status:          database.DispatchSnapshotRunning,
+		CreatedAt:       time.Now().UTC(),
+	}
+
+	if gs := projectGitService(proj); gs != nil {
+		if wt, err := gs.Snapshot(context.Background()); err != nil {
+			dispatchLog.Warn("failed to snapshot working copy", "bead_id", bead.ID, "error", err)
+		} else if raw, err := json.Marshal(wt); err == nil {
+			snap.WorktreeJSON = string(raw)
+		}
+	}
+	return snap
+}
+
+// saveDispatchSnapshot persists a snapshot, logging rather than failing
+// the dispatch on error
+func (d *Dispatcher) saveDispatchSnapshot(snap *database.DispatchSnapshot) {
+	if d.db == nil || snap == nil {
+		return
+	}
+	if err := d.db.SaveDispatchSnapshot(snap); err != nil {
+		dispatchLog.Warn("failed to save dispatch snapshot", "bead_id", snap.BeadID, "error", err)
+	}
+}
+
+// completeDispatchSnapshot records the commits, branches and PR a finished
+// dispatch produced. Commits are found both in the action results and by
+// their bead trailer, so commits made through run_command are covered. It
+// returns the dispatch's diff when one was taken.
+func (d *Dispatcher) completeDispatchSnapshot(snap *database.DispatchSnapshot, proj *models.Project, result *worker.TaskResult) string {
+	if d.db == nil || snap == nil {
+		return ""
+	}
+
+	var reported []string
+	if result != nil {
+		for _, r := range result.Actions {
+			if r.Status != "executed" || r.Metadata == nil {
+				continue
+			}
+			switch r.ActionType {
+			case actions.ActionGitCommit, actions.ActionGitMerge:
+				if sha, ok := r.Metadata["commit_sha"].(string); ok && sha != "" {
+					reported = append(reported, sha)
+				}
+			case actions.ActionGitRevert:
+				if sha, ok := r.Metadata["new_commit_sha"].(string); ok && sha != "" {
+					reported = append(reported, sha)
+				}
+			case actions.ActionCreatePR:
+				snap.PRNumber = metadataInt(r.Metadata["pr_number"])
+				snap.PRURL, _ = r.Metadata["pr_url"].(string)
+			}
+		}
+	}
+
+	var commits []string
+	var diff string
+	if wt := snapshotWorktree(snap); wt != nil {
+		if gs := projectGitService(proj); gs != nil {
+			ctx := context.Background()
+			found, err := gs.BeadCommitsSince(ctx, wt, snap.BeadID)
+			if err != nil {
+				dispatchLog.WarnContext(ctx, "failed to find commits of dispatch", "dispatch_id", snap.ID, "bead_id", snap.BeadID, "error", err)
+			}
+			// rev-list lists newest first; store oldest first like the action log
+			for i := len(found) - 1; i >= 0; i-- {
+				commits = append(commits, found[i])
+			}
+			if branches, err := gs.NewBranchesSince(ctx, wt, snap.BeadID); err != nil {
+				dispatchLog.WarnContext(ctx, "failed to find branches of dispatch", "dispatch_id", snap.ID, "bead_id", snap.BeadID, "error", err)
+			} else {
+				snap.Branches = branches
+			}
+			diff = d.dispatchDiff(ctx, gs, wt, snap)
+		}
+	}
+	for _, sha := range reported {
+		if !containsString(commits, sha) {
+			commits = append(commits, sha)
+		}
+	}
+	snap.CommitSHAs = commits
+
+	now := time.Now().UTC()
+	snap.CompletedAt = &now
+	snap.Status = database.DispatchSnapshotCompleted
+	d.saveDispatchSnapshot(snap)
+	return diff
+}
+
+// dispatchDiff returns the working copy changes of a dispatch, storing
+// them as a run artifact. It diffs only when the diff is recorded or
+// judged.
+func (d *Dispatcher) dispatchDiff(ctx context.Context, gs *git.GitService, wt *git.WorktreeSnapshot, snap *database.DispatchSnapshot) string {
+	if d.artifacts == nil && d.judge == nil {
+		return ""
+	}
+	diff, err := gs.DiffSince(ctx, wt)
+	if err != nil {
+		dispatchLog.WarnContext(ctx, "failed to diff dispatch", "dispatch_id", snap.ID, "bead_id", snap.BeadID, "error", err)
+		return ""
+	}
+	if d.artifacts != nil {
+		ref := artifacts.Ref{DispatchID: snap.ID, BeadID: snap.BeadID, ProjectID: snap.ProjectID}
+		if _, err := d.artifacts.Record(ref, artifacts.KindDiff, "", 0, []byte(diff)); err != nil {
+			dispatchLog.WarnContext(ctx, "failed to record diff of dispatch", "dispatch_id", snap.ID, "bead_id", snap.BeadID, "error", err)
+		}
+	}
+	return diff
+}
+
+// ListDispatchSnapshots returns the recorded dispatches of a bead, oldest first
+func (d *Dispatcher) ListDispatchSnapshots(beadID string) ([]*database.DispatchSnapshot, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("dispatch history requires a database")
+	}
+	return d.db.ListDispatchSnapshots(beadID)
+}
+
+// RevertDispatch rolls back everything one dispatch of a bead changed: it
+// closes the PR the dispatch opened, deletes the branches it created,
+// reverts its commits that remain on the original branch, restores the
+// working copy, and puts the bead back in the state it was in before the
+// dispatch. Later dispatches of the bead must be reverted first.
+func (d *Dispatcher) RevertDispatch(ctx context.Context, beadID, dispatchID, actor string) (*RevertDispatchResult, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("dispatch rollback requires a database")
+	}
+
+	snap, err := d.db.GetDispatchSnapshot(dispatchID)
+	if err != nil {
+		return nil, err
+	}
+	if snap == nil || snap.BeadID != beadID {
+		return nil, fmt.Errorf("dispatch %s not found for bead %s", dispatchID, beadID)
+	}
+	switch snap.Status {
+	case database.DispatchSnapshotReverted:
+		return nil, fmt.Errorf("dispatch %s is already reverted", dispatchID)
+	case database.DispatchSnapshotRunning:
+		return nil, fmt.Errorf("dispatch %s is still running", dispatchID)
+	}
+
+	history, err := d.db.ListDispatchSnapshots(beadID)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range history {
+		if other.ID != snap.ID && other.Status != database.DispatchSnapshotReverted && other.CreatedAt.After(snap.CreatedAt) {
+			return nil, fmt.Errorf("dispatch %s has a later dispatch %s that must be reverted first", dispatchID, other.ID)
+		}
+	}
+
+	result := &RevertDispatchResult{DispatchID: snap.ID, BeadID: beadID}
+
+	if wt := snapshotWorktree(snap); wt != nil {
+		proj, _ := d.projects.GetProject(snap.ProjectID)
+		gs := projectGitService(proj)
+		if gs == nil {
+			return nil, fmt.Errorf("project %s has no working copy to roll back", snap.ProjectID)
+		}
+		if err := d.revertGitChanges(ctx, gs, snap, wt, result); err != nil {
+			return nil, err
+		}
+	}
+
+	bead, err := d.restoreBead(snap)
+	if err != nil {
+		return nil, err
+	}
+	result.Bead = bead
+
+	if err := d.db.DeleteDispatchCheckpoint(beadID); err != nil {
+		dispatchLog.Warn("failed to clear dispatch checkpoint", "bead_id", beadID, "error", err)
+	}
+
+	now := time.Now().UTC()
+	snap.Status = database.DispatchSnapshotReverted
+	snap.RevertedAt = &now
+	snap.RevertedBy = actor
+	if err := d.db.SaveDispatchSnapshot(snap); err != nil {
+		return nil, err
+	}
+
+	observability.SecurityAudit("dispatch.reverted", map[string]interface{}{
+		"actor":            actor,
+		"bead_id":          beadID,
+		"project_id":       snap.ProjectID,
+		"dispatch_id":      snap.ID,
+		"closed_pr":        result.ClosedPR,
+		"deleted_branches": result.DeletedBranches,
+		"reverted_commits": result.RevertedCommits,
+	})
+	if d.eventBus != nil {
+		if err := d.eventBus.PublishBeadEvent(eventbus.EventTypeBeadStatusChange, beadID, snap.ProjectID, map[string]interface{}{"status": snap.BeadStatus}); err != nil {
+			dispatchLog.Warn("failed to publish bead status change event", "bead_id", beadID, "error", err)
+		}
+	}
+
+	return result, nil
+}
+
+// revertGitChanges undoes a dispatch's PR, branches and commits, then
+// restores the working copy captured before the dispatch. Commits that
+// only lived on the deleted branches go with them.
+func (d *Dispatcher) revertGitChanges(ctx context.Context, gs *git.GitService, snap *database.DispatchSnapshot, wt *git.WorktreeSnapshot, result *RevertDispatchResult) error {
+	if snap.PRNumber > 0 {
+		if err := gs.ClosePR(ctx, git.ClosePRRequest{
+			Number:  snap.PRNumber,
+			BeadID:  snap.BeadID,
+			Comment: fmt.Sprintf("Closed by rollback of dispatch %s of bead %s", snap.ID, snap.BeadID),
+		}); err != nil {
+			return err
+		}
+		result.ClosedPR = snap.PRNumber
+	}
+
+	if err := gs.DiscardChanges(ctx, snap.BeadID); err != nil {
+		return err
+	}
+	if _, err := gs.Checkout(ctx, git.CheckoutRequest{Branch: wt.Branch}); err != nil {
+		return err
+	}
+
+	for _, branch := range snap.Branches {
+		if branch == wt.Branch {
+			continue
+		}
+		if _, err := gs.DeleteBranch(ctx, git.DeleteBranchRequest{Branch: branch, DeleteRemote: true}); err != nil {
+			return err
+		}
+		result.DeletedBranches = append(result.DeletedBranches, branch)
+	}
+
+	var onBranch []string
+	for i := len(snap.CommitSHAs) - 1; i >= 0; i-- {
+		sha := snap.CommitSHAs[i]
+		reachable, err := gs.IsAncestor(ctx, sha, "HEAD")
+		if err != nil {
+			return err
+		}
+		if reachable {
+			onBranch = append(onBranch, sha)
+		}
+	}
+	if len(onBranch) > 0 {
+		if _, err := gs.Revert(ctx, git.RevertRequest{
+			CommitSHAs: onBranch,
+			BeadID:     snap.BeadID,
+			Reason:     fmt.Sprintf("rollback of dispatch %s", snap.ID),
+		}); err != nil {
+			return err
+		}
+		result.RevertedCommits = onBranch
+		if gs.HasUpstream(ctx) {
+			if _, err := gs.Push(ctx, git.PushRequest{BeadID: snap.BeadID, Branch: wt.Branch}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := gs.RestoreWorktree(ctx, wt, snap.BeadID); err != nil {
+		return err
+	}
+	result.RestoredWorktree = true
+	return nil
+}
+
+// restoreBead puts the bead's status, assignee and context back to what
+// they were before the dispatch. Context keys added since are blanked.
+func (d *Dispatcher) restoreBead(snap *database.DispatchSnapshot) (*models.Bead, error) {
+	bead, err := d.beads.GetBead(snap.BeadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var prior map[string]string
+	if err := json.Unmarshal([]byte(snap.BeadContextJSON), &prior); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot context of bead %s: %w", snap.BeadID, err)
+	}
+	ctxUpdates := make(map[string]string, len(bead.Context)+len(prior))
+	for k := range bead.Context {
+		ctxUpdates[k] = ""
+	}
+	for k, v := range prior {
+		ctxUpdates[k] = v
+	}
+
+	updates := map[string]interface{}{
+		"status":      models.BeadStatus(snap.BeadStatus),
+		"assigned_to": snap.BeadAssignedTo,
+		"context":     ctxUpdates,
+	}
+	if err := d.beads.UpdateBead(snap.BeadID, updates); err != nil {
+		return nil, fmt.Errorf("failed to restore bead %s: %w", snap.BeadID, err)
+	}
+	return d.beads.GetBead(snap.BeadID)
+}
+
+// snapshotWorktree decodes the working copy snapshot taken before a
+// dispatch, or returns nil if none was taken
+func snapshotWorktree(snap *database.DispatchSnapshot) *git.WorktreeSnapshot {
+	if snap.WorktreeJSON == "" {
+		return nil
+	}
+	var wt git.WorktreeSnapshot
+	if err := json.Unmarshal([]byte(snap.WorktreeJSON), &wt); err != nil {
+		dispatchLog.Warn("failed to decode working copy snapshot", "dispatch_id", snap.ID, "bead_id", snap.BeadID, "error", err)
+		return nil
+	}
+	return &wt
+}
+
+// projectGitService opens the project's working copy, or returns nil if
+// it has none
+func projectGitService(proj *models.Project) *git.GitService {
+	if proj == nil || proj.WorkDir == "" {
+		return nil
+	}
+	gs, err := git.NewGitService(proj.WorkDir, proj.ID)
+	if err != nil {
+		return nil
+	}
+	return gs
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// metadataInt reads an action result number, which is a float64 once the
+// action log has been through a checkpoint
+func metadataInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case float64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,91 @@
+package dispatch
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/beads"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestRevertDispatch_RestoresBeadState(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	defer db.Close()
+
+	beadsMgr := beads.NewManager("")
+	beadsMgr.SetBeadsPath(t.TempDir())
+	bead, err := beadsMgr.CreateBead("Fix parser", "", models.BeadPriorityP2, "task", "proj-1")
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	if err := beadsMgr.UpdateBead(bead.ID, map[string]interface{}{"context": map[string]string{"hint": "before"}}); err != nil {
+		t.Fatalf("UpdateBead failed: %v", err)
+	}
+	bead, _ = beadsMgr.GetBead(bead.ID)
+
+	d := &Dispatcher{beads: beadsMgr, db: db}
+
+	// First dispatch: snapshot, then the agent claims and changes the bead
+	first := d.captureDispatchSnapshot(bead, nil, "agent-1")
+	first.ID = "task-1"
+	first.DispatchNumber = 1
+	first.CreatedAt = time.Now().Add(-time.Minute)
+	d.saveDispatchSnapshot(first)
+	if err := beadsMgr.UpdateBead(bead.ID, map[string]interface{}{
+		"status":      models.BeadStatusInProgress,
+		"assigned_to": "agent-1",
+		"context":     map[string]string{"hint": "after", "agent_output": "done"},
+	}); err != nil {
+		t.Fatalf("UpdateBead failed: %v", err)
+	}
+
+	if _, err := d.RevertDispatch(context.Background(), bead.ID, "task-1", "ceo"); err == nil || !strings.Contains(err.Error(), "still running") {
+		t.Fatalf("expected running dispatch to be refused, got %v", err)
+	}
+	d.completeDispatchSnapshot(first, nil, nil)
+
+	// A later dispatch blocks reverting the first until it is reverted
+	current, _ := beadsMgr.GetBead(bead.ID)
+	second := d.captureDispatchSnapshot(current, nil, "agent-1")
+	second.ID = "task-2"
+	second.DispatchNumber = 2
+	d.saveDispatchSnapshot(second)
+	d.completeDispatchSnapshot(second, nil, nil)
+
+	if _, err := d.RevertDispatch(context.Background(), bead.ID, "task-1", "ceo"); err == nil || !strings.Contains(err.Error(), "must be reverted first") {
+		t.Fatalf("expected later dispatch to block the revert, got %v", err)
+	}
+	if _, err := d.RevertDispatch(context.Background(), bead.ID, "task-2", "ceo"); err != nil {
+		t.Fatalf("RevertDispatch(task-2) failed: %v", err)
+	}
+	result, err := d.RevertDispatch(context.Background(), bead.ID, "task-1", "ceo")
+	if err != nil {
+		t.Fatalf("RevertDispatch(task-1) failed: %v", err)
+	}
+
+	restored := result.Bead
+	if restored.Status != models.BeadStatusOpen || restored.AssignedTo != "" {
+		t.Errorf("expected open unassigned bead, got %s assigned to %q", restored.Status, restored.AssignedTo)
+	}
+	if restored.Context["hint"] != "before" || restored.Context["agent_output"] != "" {
+		t.Errorf("expected prior context, got %v", restored.Context)
+	}
+
+	snap, _ := db.GetDispatchSnapshot("task-1")
+	if snap.Status != database.DispatchSnapshotReverted || snap.RevertedBy != "ceo" || snap.RevertedAt == nil {
+		t.Errorf("expected snapshot marked reverted, got %+v", snap)
+	}
+	if _, err := d.RevertDispatch(context.Background(), bead.ID, "task-1", "ceo"); err == nil || !strings.Contains(err.Error(), "already reverted") {
+		t.Errorf("expected second revert to be refused, got %v", err)
+	}
+	if _, err := d.RevertDispatch(context.Background(), "other-bead", "task-1", "ceo"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected dispatch of another bead to be not found, got %v", err)
+	}
+}
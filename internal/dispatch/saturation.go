@@ -0,0 +1,87 @@
+package dispatch
+
+import (
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// ScaleUpHint is the hint provider.saturated events carry: the provider's
+// inference cluster needs more capacity
+const ScaleUpHint = "scale_up"
+
+// unsaturatedProvider returns the best active provider for complexity whose
+// inference server is not saturated and that may serve proj, which may be
+// nil
+func (d *Dispatcher) unsaturatedProvider(complexity provider.ComplexityLevel, proj *models.Project) (string, bool) {
+	var constraints *models.ComplianceConstraints
+	var orgID string
+	if proj != nil {
+		constraints = proj.Compliance
+		orgID = proj.OrgID
+	}
+	for _, candidates := range [][]*provider.RegisteredProvider{
+		d.providers.ListActiveForComplexity(complexity),
+		d.providers.ListActive(),
+	} {
+		for _, p := range candidates {
+			if p == nil || p.Config == nil || d.providers.IsSaturated(p.Config.ID) {
+				continue
+			}
+			if ProviderServesProject(p.Config, constraints, orgID) {
+				return p.Config.ID, true
+			}
+		}
+	}
+	return "", false
+}
+
+// reportSaturation publishes provider.saturated, with a scale-up hint, when
+// a provider's inference server becomes saturated, and provider.recovered
+// when it has capacity again
+func (d *Dispatcher) reportSaturation(providers []*provider.RegisteredProvider) {
+	for _, p := range providers {
+		if p == nil || p.Config == nil {
+			continue
+		}
+		id := p.Config.ID
+		saturated := d.providers.IsSaturated(id)
+
+		d.mu.Lock()
+		if d.saturated == nil {
+			d.saturated = make(map[string]bool)
+		}
+		was := d.saturated[id]
+		if saturated {
+			d.saturated[id] = true
+		} else {
+			delete(d.saturated, id)
+		}
+		d.mu.Unlock()
+
+		if saturated == was || d.eventBus == nil {
+			continue
+		}
+		load, _ := d.providers.ServerLoad(id)
+		name := p.Config.Name
+		if name == "" {
+			name = id
+		}
+		data := map[string]interface{}{
+			"provider_id": id,
+			"name":        name,
+			"running":     load.Running,
+			"waiting":     load.Waiting,
+			"cache_usage": load.CacheUsage,
+		}
+		eventType := eventbus.EventTypeProviderRecovered
+		if saturated {
+			eventType = eventbus.EventTypeProviderSaturated
+			data["hint"] = ScaleUpHint
+			dispatchLog.Warn("provider saturated", "provider_id", id, "waiting", load.Waiting, "cache_usage", load.CacheUsage)
+		} else {
+			dispatchLog.Info("provider recovered from saturation", "provider_id", id)
+		}
+		_ = d.eventBus.Publish(&eventbus.Event{Type: eventType, Source: "dispatcher", Data: data})
+	}
+}
@@ -0,0 +1,77 @@
+package dispatch
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+func TestUnsaturatedProvider(t *testing.T) {
+	registry := provider.NewRegistry()
+	for _, cfg := range []*provider.ProviderConfig{
+		{ID: "vllm-big", Type: provider.TypeVLLM, Endpoint: "http://localhost:8000/v1", Model: "m", Status: "active", ModelParamsB: 400},
+		{ID: "tgi-small", Type: provider.TypeTGI, Endpoint: "http://localhost:8001/v1", Model: "m", Status: "active", ModelParamsB: 8},
+	} {
+		if err := registry.Register(cfg); err != nil {
+			t.Fatalf("Register %s: %v", cfg.ID, err)
+		}
+	}
+	d := NewDispatcher(nil, nil, nil, registry, nil)
+
+	registry.SetServerLoad("vllm-big", provider.ServerLoad{Waiting: 20, CheckedAt: time.Now()})
+	if id, ok := d.unsaturatedProvider(provider.ComplexityExtended, nil); !ok || id != "tgi-small" {
+		t.Errorf("expected work to be routed around the saturated server, got %q (%v)", id, ok)
+	}
+
+	registry.SetServerLoad("tgi-small", provider.ServerLoad{Running: 4, CacheUsage: 0.99, CheckedAt: time.Now()})
+	if id, ok := d.unsaturatedProvider(provider.ComplexityExtended, nil); ok {
+		t.Errorf("expected no provider with every server saturated, got %q", id)
+	}
+
+	// A load nobody refreshed is no longer trusted
+	registry.SetServerLoad("vllm-big", provider.ServerLoad{Waiting: 20, CheckedAt: time.Now().Add(-time.Hour)})
+	if id, ok := d.unsaturatedProvider(provider.ComplexityExtended, nil); !ok || id != "vllm-big" {
+		t.Errorf("expected a stale load to be ignored, got %q (%v)", id, ok)
+	}
+}
+
+func TestReportSaturation(t *testing.T) {
+	registry := provider.NewRegistry()
+	if err := registry.Register(&provider.ProviderConfig{ID: "vllm", Name: "GPU cluster", Type: provider.TypeVLLM, Endpoint: "http://localhost:8000/v1", Status: "active"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	eb := eventbus.NewEventBus(nil, &config.TemporalConfig{EventBufferSize: 10})
+	defer eb.Close()
+	sub := eb.Subscribe("test", func(e *eventbus.Event) bool {
+		return e.Type == eventbus.EventTypeProviderSaturated || e.Type == eventbus.EventTypeProviderRecovered
+	})
+	d := NewDispatcher(nil, nil, nil, registry, eb)
+
+	next := func() *eventbus.Event {
+		t.Helper()
+		select {
+		case e := <-sub.Channel:
+			return e
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected an event")
+			return nil
+		}
+	}
+
+	registry.SetServerLoad("vllm", provider.ServerLoad{Running: 16, Waiting: 12, CheckedAt: time.Now()})
+	d.reportSaturation(registry.ListActive())
+	d.reportSaturation(registry.ListActive()) // Still saturated: no second event
+	e := next()
+	if e.Type != eventbus.EventTypeProviderSaturated || e.Data["hint"] != ScaleUpHint || e.Data["waiting"] != 12 || e.Data["name"] != "GPU cluster" {
+		t.Errorf("unexpected saturation event %+v", e)
+	}
+
+	registry.SetServerLoad("vllm", provider.ServerLoad{Running: 3, CheckedAt: time.Now()})
+	d.reportSaturation(registry.ListActive())
+	if e := next(); e.Type != eventbus.EventTypeProviderRecovered {
+		t.Errorf("expected a recovery event, got %s", e.Type)
+	}
+}
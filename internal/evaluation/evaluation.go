@@ -0,0 +1,570 @@
+// Package evaluation scores what agents produce. A judge is a persona and
+// the criteria it grades by; when a dispatch finishes its work, every
+// judge of the project reads the bead's instructions, the agent's answer
+// and its diff, and scores each criterion. Scores are kept per provider so
+// routing can rank providers by the quality of their work, not only by
+// model size and latency.
+package evaluation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultJudgeID names the judge a project without judges of its own is
+// evaluated by
+const DefaultJudgeID = "default"
+
+// maxScore is the top of the scale judges grade each criterion on
+const maxScore = 10
+
+// maxResponseChars bounds the judge's answer kept with an evaluation
+const maxResponseChars = 8000
+
+// Criterion is one thing a judge grades
+type Criterion struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	Weight      float64 `json:"weight,omitempty"` // Relative weight in the overall score; 1 when unset
+}
+
+// DefaultCriteria grades whether the agent did what the bead asked, whether
+// the change is correct, and whether the diff is small and clean
+func DefaultCriteria() []Criterion {
+	return []Criterion{
+		{Name: "instructions", Description: "The change does what the bead asked for, all of it and nothing unrelated", Weight: 2},
+		{Name: "correctness", Description: "The change is free of bugs, handles errors and edge cases, and keeps existing behavior working", Weight: 2},
+		{Name: "diff_quality", Description: "The diff is minimal, readable, follows the surrounding code's conventions and comes with tests where they belong", Weight: 1},
+	}
+}
+
+// Judge is a persona that grades agent output by its criteria
+type Judge struct {
+	ID         string      `json:"id"`
+	ProjectID  string      `json:"project_id"`
+	Name       string      `json:"name"`
+	Persona    string      `json:"persona"`
+	ProviderID string      `json:"provider_id,omitempty"` // Empty picks a provider by the persona's model tier
+	Prompt     string      `json:"prompt,omitempty"`      // Extra instructions for the judge
+	Criteria   []Criterion `json:"criteria"`
+	Enabled    bool        `json:"enabled"`
+	CreatedBy  string      `json:"created_by,omitempty"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+// JudgeRequest creates or updates a judge. On update, nil fields are left
+// unchanged.
+type JudgeRequest struct {
+	Name       *string      `json:"name,omitempty"`
+	Persona    *string      `json:"persona,omitempty"` // Defaults to the configured judge persona on create
+	ProviderID *string      `json:"provider_id,omitempty"`
+	Prompt     *string      `json:"prompt,omitempty"`
+	Criteria   *[]Criterion `json:"criteria,omitempty"` // Defaults to DefaultCriteria on create
+	Enabled    *bool        `json:"enabled,omitempty"`  // Defaults to true on create
+}
+
+// Output is what one dispatch of a bead produced
+type Output struct {
+	ProjectID    string
+	BeadID       string
+	DispatchID   string
+	AgentID      string
+	ProviderID   string // Provider whose work is judged
+	Model        string
+	Instructions string // The bead's title and description
+	Response     string // The agent's final answer
+	Diff         string
+}
+
+// CriterionScore is a judge's grade on one criterion, from 0 to 1
+type CriterionScore struct {
+	Criterion string  `json:"criterion"`
+	Score     float64 `json:"score"`
+	Reason    string  `json:"reason,omitempty"`
+}
+
+// Evaluation is one judge's grading of one dispatch
+type Evaluation struct {
+	ID              string           `json:"id"`
+	JudgeID         string           `json:"judge_id"`
+	JudgeName       string           `json:"judge_name"`
+	ProjectID       string           `json:"project_id"`
+	BeadID          string           `json:"bead_id,omitempty"`
+	DispatchID      string           `json:"dispatch_id,omitempty"`
+	AgentID         string           `json:"agent_id,omitempty"`
+	ProviderID      string           `json:"provider_id"`
+	Model           string           `json:"model,omitempty"`
+	JudgeProviderID string           `json:"judge_provider_id,omitempty"`
+	JudgeModel      string           `json:"judge_model,omitempty"`
+	Scores          []CriterionScore `json:"scores"`
+	Score           float64          `json:"score"` // Weighted mean of the criterion scores, 0 on error
+	Summary         string           `json:"summary,omitempty"`
+	Response        string           `json:"response,omitempty"` // The judge's raw answer
+	Error           string           `json:"error,omitempty"`
+	CreatedAt       time.Time        `json:"created_at"`
+}
+
+// Filter selects evaluations
+type Filter struct {
+	ProjectID  string
+	BeadID     string
+	ProviderID string
+	Limit      int // 0 means no limit
+}
+
+// ProviderQuality is the mean score of a provider's graded work
+type ProviderQuality struct {
+	ProviderID  string  `json:"provider_id"`
+	Quality     float64 `json:"quality"` // 0 to 1
+	Evaluations int     `json:"evaluations"`
+}
+
+// Store persists judges and evaluations
+type Store interface {
+	// SaveJudge inserts or replaces a judge
+	SaveJudge(j *Judge) error
+	// GetJudge returns a judge, or nil if unknown
+	GetJudge(id string) (*Judge, error)
+	// ListJudges returns a project's judges
+	ListJudges(projectID string) ([]*Judge, error)
+	// DeleteJudge removes a judge
+	DeleteJudge(id string) error
+	// SaveEvaluation inserts an evaluation
+	SaveEvaluation(e *Evaluation) error
+	// ListEvaluations returns the evaluations matching filter, newest first
+	ListEvaluations(filter Filter) ([]*Evaluation, error)
+	// ProviderQuality returns the mean score of each provider's
+	// evaluations since a time, leaving out failed evaluations
+	ProviderQuality(since time.Time) ([]ProviderQuality, error)
+}
+
+// Completer asks a judge persona for its grading
+type Completer interface {
+	// Complete answers prompt as persona for a project, on providerID or,
+	// when empty, on a provider suited to the persona other than avoid,
+	// and names the provider and model that did
+	Complete(ctx context.Context, projectID, persona, providerID, avoid, prompt string) (response, usedProvider, model string, err error)
+}
+
+// Config tunes evaluation
+type Config struct {
+	Enabled      bool          // Judge every dispatch that finishes its work
+	Persona      string        // Persona of the default judge and of new judges
+	ProviderID   string        // Provider the default judge grades on
+	MaxDiffBytes int           // Longer diffs are truncated before grading
+	Timeout      time.Duration // Per evaluation
+	Window       time.Duration // How far back evaluations count toward provider quality
+	MinSamples   int           // Evaluations a provider needs before its quality affects ranking
+}
+
+// DefaultConfig judges as the code reviewer persona, sends at most 100KB
+// of diff, and ranks providers by their last two weeks of evaluations once
+// they have three
+func DefaultConfig() Config {
+	return Config{
+		Persona:      "default/code-reviewer",
+		MaxDiffBytes: 100000,
+		Timeout:      2 * time.Minute,
+		Window:       14 * 24 * time.Hour,
+		MinSamples:   3,
+	}
+}
+
+// Manager owns the judges and runs evaluations. Submit does nothing on a
+// nil Manager.
+type Manager struct {
+	store     Store
+	completer Completer
+	cfg       Config
+
+	mu          sync.Mutex
+	onEvaluated func(*Evaluation)
+}
+
+// NewManager creates a manager backed by store that grades through
+// completer
+func NewManager(store Store, completer Completer, cfg Config) *Manager {
+	if store == nil || completer == nil {
+		return nil
+	}
+	def := DefaultConfig()
+	if cfg.Persona == "" {
+		cfg.Persona = def.Persona
+	}
+	if cfg.MaxDiffBytes <= 0 {
+		cfg.MaxDiffBytes = def.MaxDiffBytes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = def.Window
+	}
+	if cfg.MinSamples <= 0 {
+		cfg.MinSamples = def.MinSamples
+	}
+	return &Manager{store: store, completer: completer, cfg: cfg}
+}
+
+// Config returns the configuration the manager runs with
+func (m *Manager) Config() Config {
+	return m.cfg
+}
+
+// OnEvaluated registers the function told about every stored evaluation
+func (m *Manager) OnEvaluated(fn func(*Evaluation)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvaluated = fn
+}
+
+// validate checks the fields a judge needs to grade
+func (j *Judge) validate() error {
+	if j.ProjectID == "" {
+		return fmt.Errorf("project_id must not be empty")
+	}
+	if strings.TrimSpace(j.Name) == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if strings.TrimSpace(j.Persona) == "" {
+		return fmt.Errorf("persona must not be empty")
+	}
+	if len(j.Criteria) == 0 {
+		return fmt.Errorf("criteria must list at least one criterion")
+	}
+	seen := make(map[string]bool, len(j.Criteria))
+	for i, c := range j.Criteria {
+		if strings.TrimSpace(c.Name) == "" || strings.TrimSpace(c.Description) == "" {
+			return fmt.Errorf("criterion %d needs a name and a description", i)
+		}
+		if c.Weight < 0 {
+			return fmt.Errorf("criterion %s has a negative weight", c.Name)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("criterion %s is listed twice", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// apply copies the set fields of req onto j
+func (req *JudgeRequest) apply(j *Judge) {
+	if req.Name != nil {
+		j.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Persona != nil {
+		j.Persona = strings.TrimSpace(*req.Persona)
+	}
+	if req.ProviderID != nil {
+		j.ProviderID = *req.ProviderID
+	}
+	if req.Prompt != nil {
+		j.Prompt = *req.Prompt
+	}
+	if req.Criteria != nil {
+		j.Criteria = *req.Criteria
+	}
+	if req.Enabled != nil {
+		j.Enabled = *req.Enabled
+	}
+}
+
+// Create adds a judge to a project
+func (m *Manager) Create(projectID string, req JudgeRequest, createdBy string) (*Judge, error) {
+	now := time.Now().UTC()
+	j := &Judge{
+		ID:        uuid.New().String(),
+		ProjectID: projectID,
+		Persona:   m.cfg.Persona,
+		Criteria:  DefaultCriteria(),
+		Enabled:   true,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req.apply(j)
+	if err := j.validate(); err != nil {
+		return nil, fmt.Errorf("invalid judge: %w", err)
+	}
+	if err := m.store.SaveJudge(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Get returns one of a project's judges
+func (m *Manager) Get(projectID, id string) (*Judge, error) {
+	j, err := m.store.GetJudge(id)
+	if err != nil {
+		return nil, err
+	}
+	if j == nil || j.ProjectID != projectID {
+		return nil, fmt.Errorf("judge not found: %s", id)
+	}
+	return j, nil
+}
+
+// List returns a project's judges
+func (m *Manager) List(projectID string) ([]*Judge, error) {
+	return m.store.ListJudges(projectID)
+}
+
+// Update changes the fields set in req
+func (m *Manager) Update(projectID, id string, req JudgeRequest) (*Judge, error) {
+	j, err := m.Get(projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	req.apply(j)
+	if err := j.validate(); err != nil {
+		return nil, fmt.Errorf("invalid judge: %w", err)
+	}
+	j.UpdatedAt = time.Now().UTC()
+	if err := m.store.SaveJudge(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Delete removes a judge; its evaluations are kept
+func (m *Manager) Delete(projectID, id string) error {
+	if _, err := m.Get(projectID, id); err != nil {
+		return err
+	}
+	return m.store.DeleteJudge(id)
+}
+
+// Evaluations returns the evaluations matching filter, newest first
+func (m *Manager) Evaluations(filter Filter) ([]*Evaluation, error) {
+	return m.store.ListEvaluations(filter)
+}
+
+// Quality returns the mean score of each provider's evaluations within the
+// configured window
+func (m *Manager) Quality() ([]ProviderQuality, error) {
+	return m.store.ProviderQuality(time.Now().Add(-m.cfg.Window))
+}
+
+// judges returns the enabled judges of a project, or the default judge
+// when the project has none
+func (m *Manager) judges(projectID string) ([]*Judge, error) {
+	list, err := m.store.ListJudges(projectID)
+	if err != nil {
+		return nil, err
+	}
+	var enabled []*Judge
+	for _, j := range list {
+		if j.Enabled {
+			enabled = append(enabled, j)
+		}
+	}
+	if len(list) == 0 {
+		enabled = []*Judge{{
+			ID:         DefaultJudgeID,
+			ProjectID:  projectID,
+			Name:       "Default judge",
+			Persona:    m.cfg.Persona,
+			ProviderID: m.cfg.ProviderID,
+			Criteria:   DefaultCriteria(),
+			Enabled:    true,
+		}}
+	}
+	return enabled, nil
+}
+
+// Submit grades a dispatch's output in the background when evaluation is
+// enabled
+func (m *Manager) Submit(out Output) {
+	if m == nil || !m.cfg.Enabled {
+		return
+	}
+	go func() {
+		if _, err := m.Evaluate(context.Background(), out); err != nil {
+			log.Printf("[Evaluation] Evaluation of dispatch %s failed: %v", out.DispatchID, err)
+		}
+	}()
+}
+
+// Evaluate has every judge of the project grade out now and records the
+// evaluations. A judge that could not grade is recorded with its error.
+func (m *Manager) Evaluate(ctx context.Context, out Output) ([]*Evaluation, error) {
+	if out.ProjectID == "" {
+		return nil, fmt.Errorf("project_id must not be empty")
+	}
+	if out.ProviderID == "" {
+		return nil, fmt.Errorf("provider_id must not be empty")
+	}
+	judges, err := m.judges(out.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	notify := m.onEvaluated
+	m.mu.Unlock()
+
+	var list []*Evaluation
+	for _, j := range judges {
+		e := m.evaluate(ctx, j, out)
+		if err := m.store.SaveEvaluation(e); err != nil {
+			return list, err
+		}
+		list = append(list, e)
+		if notify != nil {
+			notify(e)
+		}
+	}
+	return list, nil
+}
+
+// evaluate asks one judge to grade out
+func (m *Manager) evaluate(ctx context.Context, j *Judge, out Output) *Evaluation {
+	e := &Evaluation{
+		ID:         uuid.New().String(),
+		JudgeID:    j.ID,
+		JudgeName:  j.Name,
+		ProjectID:  out.ProjectID,
+		BeadID:     out.BeadID,
+		DispatchID: out.DispatchID,
+		AgentID:    out.AgentID,
+		ProviderID: out.ProviderID,
+		Model:      out.Model,
+		Scores:     []CriterionScore{},
+		CreatedAt:  time.Now().UTC(),
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	response, providerID, model, err := m.completer.Complete(cctx, out.ProjectID, j.Persona, j.ProviderID, out.ProviderID, m.prompt(j, out))
+	e.JudgeProviderID = providerID
+	e.JudgeModel = model
+	if err != nil {
+		e.Error = fmt.Sprintf("judge failed: %v", err)
+		return e
+	}
+	if len(response) > maxResponseChars {
+		e.Response = response[:maxResponseChars]
+	} else {
+		e.Response = response
+	}
+
+	summary, scores, err := ParseScores(response, j.Criteria)
+	if err != nil {
+		e.Error = err.Error()
+		return e
+	}
+	e.Summary = summary
+	e.Scores = scores
+	e.Score = WeightedScore(j.Criteria, scores)
+	return e
+}
+
+// prompt asks the judge to grade out by its criteria, with the diff
+// truncated to the configured size
+func (m *Manager) prompt(j *Judge, out Output) string {
+	diff := out.Diff
+	truncated := ""
+	if len(diff) > m.cfg.MaxDiffBytes {
+		diff = diff[:m.cfg.MaxDiffBytes]
+		truncated = "\n(The diff was truncated; grade what is shown.)\n"
+	}
+	var sb strings.Builder
+	sb.WriteString("Grade the work an agent did on a task.\n\n")
+	sb.WriteString("## Task\n\n")
+	sb.WriteString(strings.TrimSpace(out.Instructions))
+	sb.WriteString("\n\n")
+	if strings.TrimSpace(out.Response) != "" {
+		sb.WriteString("## The agent's final answer\n\n")
+		sb.WriteString(strings.TrimSpace(out.Response))
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("## Criteria\n\n")
+	for _, c := range j.Criteria {
+		sb.WriteString(fmt.Sprintf("- %s: %s\n", c.Name, c.Description))
+	}
+	if strings.TrimSpace(j.Prompt) != "" {
+		sb.WriteString("\n")
+		sb.WriteString(strings.TrimSpace(j.Prompt))
+		sb.WriteString("\n")
+	}
+	sb.WriteString(fmt.Sprintf("\nScore every criterion from 0 (not met at all) to %d (fully met). Answer with JSON only, in this shape:\n", maxScore))
+	sb.WriteString(`{"summary": "one paragraph", "scores": [{"criterion": "name", "score": 7, "reason": "why"}]}`)
+	sb.WriteString("\n\n```diff\n")
+	sb.WriteString(diff)
+	sb.WriteString("\n```\n")
+	sb.WriteString(truncated)
+	return sb.String()
+}
+
+// ParseScores reads the judge's JSON answer, which may be wrapped in a
+// code fence or surrounded by prose, and scales its grades to 0..1. Scores
+// for criteria the judge does not have are dropped, and every criterion
+// must be graded.
+func ParseScores(response string, criteria []Criterion) (string, []CriterionScore, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return "", nil, fmt.Errorf("judge answer has no JSON object")
+	}
+	var answer struct {
+		Summary string `json:"summary"`
+		Scores  []struct {
+			Criterion string  `json:"criterion"`
+			Score     float64 `json:"score"`
+			Reason    string  `json:"reason"`
+		} `json:"scores"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &answer); err != nil {
+		return "", nil, fmt.Errorf("failed to decode judge answer: %w", err)
+	}
+
+	graded := make(map[string]CriterionScore, len(answer.Scores))
+	for _, s := range answer.Scores {
+		name := strings.TrimSpace(s.Criterion)
+		graded[name] = CriterionScore{
+			Criterion: name,
+			Score:     math.Max(0, math.Min(s.Score, maxScore)) / maxScore,
+			Reason:    strings.TrimSpace(s.Reason),
+		}
+	}
+	scores := make([]CriterionScore, 0, len(criteria))
+	for _, c := range criteria {
+		s, ok := graded[c.Name]
+		if !ok {
+			return "", nil, fmt.Errorf("judge did not score %s", c.Name)
+		}
+		scores = append(scores, s)
+	}
+	return strings.TrimSpace(answer.Summary), scores, nil
+}
+
+// WeightedScore is the mean of scores weighted by their criteria
+func WeightedScore(criteria []Criterion, scores []CriterionScore) float64 {
+	weights := make(map[string]float64, len(criteria))
+	for _, c := range criteria {
+		w := c.Weight
+		if w == 0 {
+			w = 1
+		}
+		weights[c.Name] = w
+	}
+	var sum, total float64
+	for _, s := range scores {
+		sum += weights[s.Criterion] * s.Score
+		total += weights[s.Criterion]
+	}
+	if total == 0 {
+		return 0
+	}
+	return sum / total
+}
@@ -0,0 +1,166 @@
+package evaluation_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/evaluation"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "evaluation.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakeCompleter answers every persona with its scripted answer
+type fakeCompleter struct {
+	mu      sync.Mutex
+	answers map[string]string // persona -> answer
+	avoided []string
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, projectID, persona, providerID, avoid, prompt string) (string, string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.avoided = append(f.avoided, avoid)
+	if !strings.Contains(prompt, "+fixed") {
+		return "", "", "", fmt.Errorf("prompt is missing the diff")
+	}
+	answer, ok := f.answers[persona]
+	if !ok {
+		return "", "", "", fmt.Errorf("unknown persona %s", persona)
+	}
+	return answer, "judge-provider", "judge-model", nil
+}
+
+var testOutput = evaluation.Output{
+	ProjectID: "proj-1", BeadID: "bd-1", DispatchID: "dsp-1", AgentID: "agent-1",
+	ProviderID: "worker-provider", Model: "m1", Instructions: "Fix the bug", Diff: "+fixed\n",
+}
+
+func TestParseScores(t *testing.T) {
+	criteria := []evaluation.Criterion{{Name: "correctness", Description: "correct", Weight: 3}, {Name: "style", Description: "clean"}}
+	summary, scores, err := evaluation.ParseScores("My grades:\n```json\n"+
+		`{"summary": "Good.", "scores": [{"criterion": "correctness", "score": 8, "reason": "works"},`+
+		`{"criterion": "style", "score": 14}, {"criterion": "speed", "score": 1}]}`+"\n```", criteria)
+	if err != nil {
+		t.Fatalf("ParseScores failed: %v", err)
+	}
+	if summary != "Good." || len(scores) != 2 || scores[0].Score != 0.8 || scores[1].Score != 1 {
+		t.Fatalf("got %q, %+v", summary, scores)
+	}
+	if got := evaluation.WeightedScore(criteria, scores); got < 0.849 || got > 0.851 {
+		t.Errorf("WeightedScore = %f, want 0.85", got)
+	}
+
+	if _, _, err := evaluation.ParseScores(`{"scores": [{"criterion": "style", "score": 5}]}`, criteria); err == nil {
+		t.Error("expected an error when a criterion is not scored")
+	}
+	if _, _, err := evaluation.ParseScores("Looks fine", criteria); err == nil {
+		t.Error("expected an error for an answer without JSON")
+	}
+}
+
+func TestEvaluate_DefaultJudge(t *testing.T) {
+	db := newTestDB(t)
+	completer := &fakeCompleter{answers: map[string]string{
+		"default/code-reviewer": `{"summary": "ok", "scores": [{"criterion": "instructions", "score": 10}, {"criterion": "correctness", "score": 5}, {"criterion": "diff_quality", "score": 5}]}`,
+	}}
+	mgr := evaluation.NewManager(db, completer, evaluation.Config{Enabled: true})
+
+	var notified []*evaluation.Evaluation
+	mgr.OnEvaluated(func(e *evaluation.Evaluation) { notified = append(notified, e) })
+
+	list, err := mgr.Evaluate(context.Background(), testOutput)
+	if err != nil || len(list) != 1 {
+		t.Fatalf("Evaluate = %+v, %v", list, err)
+	}
+	e := list[0]
+	if e.JudgeID != evaluation.DefaultJudgeID || e.Error != "" || e.Score != 0.7 || e.JudgeProviderID != "judge-provider" {
+		t.Errorf("unexpected evaluation: %+v", e)
+	}
+	if len(notified) != 1 || completer.avoided[0] != "worker-provider" {
+		t.Errorf("expected one notification and the judged provider avoided, got %d, %v", len(notified), completer.avoided)
+	}
+
+	quality, err := mgr.Quality()
+	if err != nil || len(quality) != 1 || quality[0].ProviderID != "worker-provider" || quality[0].Evaluations != 1 {
+		t.Errorf("Quality = %+v, %v", quality, err)
+	}
+}
+
+func TestEvaluate_ProjectJudges(t *testing.T) {
+	db := newTestDB(t)
+	completer := &fakeCompleter{answers: map[string]string{
+		"default/qa-engineer": `{"scores": [{"criterion": "tests", "score": 6}]}`,
+	}}
+	mgr := evaluation.NewManager(db, completer, evaluation.Config{})
+
+	name, persona := "QA", "default/qa-engineer"
+	criteria := []evaluation.Criterion{{Name: "tests", Description: "The change is tested"}}
+	qa, err := mgr.Create("proj-1", evaluation.JudgeRequest{Name: &name, Persona: &persona, Criteria: &criteria}, "u-1")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	other := "Broken"
+	broken, err := mgr.Create("proj-1", evaluation.JudgeRequest{Name: &other, Persona: &other}, "u-1")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	empty := ""
+	if _, err := mgr.Create("proj-1", evaluation.JudgeRequest{Name: &empty}, "u-1"); err == nil || !strings.Contains(err.Error(), "invalid judge") {
+		t.Errorf("expected a nameless judge to be rejected, got %v", err)
+	}
+	if _, err := mgr.Get("proj-2", qa.ID); err == nil {
+		t.Error("expected a judge to be hidden from other projects")
+	}
+
+	list, err := mgr.Evaluate(context.Background(), testOutput)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("Evaluate = %+v, %v", list, err)
+	}
+	if list[0].JudgeID != qa.ID || list[0].Score != 0.6 {
+		t.Errorf("unexpected QA evaluation: %+v", list[0])
+	}
+	if list[1].JudgeID != broken.ID || list[1].Error == "" {
+		t.Errorf("expected the broken judge's failure to be recorded: %+v", list[1])
+	}
+
+	disabled := false
+	if _, err := mgr.Update("proj-1", broken.ID, evaluation.JudgeRequest{Enabled: &disabled}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if list, _ := mgr.Evaluate(context.Background(), testOutput); len(list) != 1 {
+		t.Errorf("expected disabled judges to be skipped, got %d evaluations", len(list))
+	}
+
+	stored, err := mgr.Evaluations(evaluation.Filter{ProjectID: "proj-1", BeadID: "bd-1"})
+	if err != nil || len(stored) != 3 {
+		t.Errorf("Evaluations = %d, %v", len(stored), err)
+	}
+	quality, _ := mgr.Quality()
+	if len(quality) != 1 || quality[0].Evaluations != 2 {
+		t.Errorf("expected failed evaluations left out of quality, got %+v", quality)
+	}
+}
+
+func TestSubmit_NilAndDisabled(t *testing.T) {
+	var mgr *evaluation.Manager
+	mgr.Submit(testOutput)
+
+	completer := &fakeCompleter{}
+	evaluation.NewManager(newTestDB(t), completer, evaluation.Config{}).Submit(testOutput)
+	if len(completer.avoided) != 0 {
+		t.Error("expected a disabled manager not to evaluate")
+	}
+}
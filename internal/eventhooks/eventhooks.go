@@ -0,0 +1,629 @@
+// Package eventhooks delivers system activity events to external services.
+// Unlike per-user notification webhooks, a subscription here belongs to the
+// system: it names the event types (and optionally the projects) it wants,
+// and every matching event is POSTed to its URL, signed with the
+// subscription's secret. Each delivery is logged and retried with
+// exponential backoff until the endpoint accepts it or attempts run out.
+package eventhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"   // Waiting for its first or next attempt
+	StatusDelivered = "delivered" // The endpoint answered 2xx
+	StatusFailed    = "failed"    // Attempts ran out or the endpoint rejected it
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEvent     = "X-Loom-Event"
+	HeaderDelivery  = "X-Loom-Delivery"
+	HeaderTimestamp = "X-Loom-Timestamp"
+	HeaderSignature = "X-Loom-Signature-256"
+)
+
+// EventPing is sent by Test to check an endpoint
+const EventPing = "ping"
+
+// typeAliases lets subscriptions use the names integrators expect for
+// events the activity feed reports under another name
+var typeAliases = map[string]string{
+	"bead.closed": "bead.completed", // bead.completed is published when a bead is closed
+}
+
+// Subscription is an external endpoint subscribed to activity events
+type Subscription struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`      // Only returned when the subscription is created
+	EventTypes []string  `json:"event_types"`           // e.g. bead.completed, workflow.*, or * for all
+	ProjectIDs []string  `json:"project_ids,omitempty"` // Empty delivers events of every project
+	Enabled    bool      `json:"enabled"`
+	CreatedBy  string    `json:"created_by,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SubscriptionRequest creates or updates a subscription. On update, nil
+// fields are left unchanged.
+type SubscriptionRequest struct {
+	Name       *string   `json:"name,omitempty"`
+	URL        *string   `json:"url,omitempty"`
+	Secret     *string   `json:"secret,omitempty"` // Generated on create when omitted
+	EventTypes []string  `json:"event_types,omitempty"`
+	ProjectIDs *[]string `json:"project_ids,omitempty"`
+	Enabled    *bool     `json:"enabled,omitempty"` // Defaults to true on create
+}
+
+// Event is the body POSTed to subscribers
+type Event struct {
+	ID        string                 `json:"id"`
+	Type      string                 `json:"type"`
+	Timestamp time.Time              `json:"timestamp"`
+	ProjectID string                 `json:"project_id,omitempty"`
+	Data      map[string]interface{} `json:"data,omitempty"`
+}
+
+// Delivery is one event sent to one subscription, with the outcome of its
+// latest attempt
+type Delivery struct {
+	ID             string     `json:"id"`
+	SubscriptionID string     `json:"subscription_id"`
+	EventID        string     `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	ProjectID      string     `json:"project_id,omitempty"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseCode   int        `json:"response_code,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// DeliveryFilter selects deliveries from the log, newest first unless
+// Ascending is set
+type DeliveryFilter struct {
+	SubscriptionID string
+	Status         string
+	Ascending      bool
+	Limit          int // 0 means no limit
+	Offset         int
+	After          []interface{} // (created_at, id) of the last delivery of the previous page
+}
+
+// Store persists subscriptions and the delivery log
+type Store interface {
+	// SaveEventWebhook inserts or replaces a subscription
+	SaveEventWebhook(s *Subscription) error
+	// GetEventWebhook returns a subscription, or nil if unknown
+	GetEventWebhook(id string) (*Subscription, error)
+	ListEventWebhooks() ([]*Subscription, error)
+	// DeleteEventWebhook removes a subscription and its delivery log
+	DeleteEventWebhook(id string) error
+	// SaveWebhookDelivery inserts or replaces a delivery
+	SaveWebhookDelivery(d *Delivery) error
+	// GetWebhookDelivery returns a delivery, or nil if unknown
+	GetWebhookDelivery(id string) (*Delivery, error)
+	// ListWebhookDeliveries returns a page of deliveries and the total matching f
+	ListWebhookDeliveries(f DeliveryFilter) ([]*Delivery, int, error)
+}
+
+// Config tunes delivery
+type Config struct {
+	MaxAttempts    int           // Attempts before a delivery is marked failed
+	InitialBackoff time.Duration // Wait before the first retry; doubles on each retry
+	MaxBackoff     time.Duration
+	Timeout        time.Duration // Per-attempt HTTP timeout
+}
+
+// DefaultConfig makes five attempts spread over roughly fifteen minutes
+func DefaultConfig() Config {
+	return Config{
+		MaxAttempts:    5,
+		InitialBackoff: time.Minute,
+		MaxBackoff:     time.Hour,
+		Timeout:        10 * time.Second,
+	}
+}
+
+// Manager owns the subscriptions and sends deliveries. A nil Manager
+// delivers nothing.
+type Manager struct {
+	store  Store
+	cfg    Config
+	client *http.Client
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer // Scheduled retries by delivery ID
+	closed bool
+}
+
+// NewManager creates a manager backed by store
+func NewManager(store Store, cfg Config) *Manager {
+	if store == nil {
+		return nil
+	}
+	if cfg.MaxAttempts <= 0 {
+		cfg.MaxAttempts = DefaultConfig().MaxAttempts
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig().Timeout
+	}
+	return &Manager{
+		store:  store,
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Sign returns the signature header value for a delivery body sent at
+// timestamp (Unix seconds): "sha256=" followed by the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed by the subscription secret
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is the signature of body sent at timestamp
+func Verify(secret, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Matches reports whether the subscription wants ev
+func (s *Subscription) Matches(ev Event) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.ProjectIDs) > 0 && !slices.Contains(s.ProjectIDs, ev.ProjectID) {
+		return false
+	}
+	for _, pattern := range s.EventTypes {
+		if matchType(pattern, ev.Type) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchType matches an event type against an exact type, a "prefix.*"
+// pattern or "*"
+func matchType(pattern, eventType string) bool {
+	if alias, ok := typeAliases[pattern]; ok {
+		pattern = alias
+	}
+	switch {
+	case pattern == "*":
+		return true
+	case strings.HasSuffix(pattern, ".*"):
+		return strings.HasPrefix(eventType, strings.TrimSuffix(pattern, "*"))
+	default:
+		return pattern == eventType
+	}
+}
+
+// redacted returns a copy of s without its secret
+func (s *Subscription) redacted() *Subscription {
+	cp := *s
+	cp.Secret = ""
+	return &cp
+}
+
+// validate checks the fields a subscription needs to be delivered to
+func (s *Subscription) validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+	if len(s.EventTypes) == 0 {
+		return fmt.Errorf("event_types must name at least one event type")
+	}
+	for _, t := range s.EventTypes {
+		if t == "" || strings.ContainsAny(t, " \t\n") {
+			return fmt.Errorf("invalid event type %q", t)
+		}
+	}
+	if s.Secret == "" {
+		return fmt.Errorf("secret must not be empty")
+	}
+	return nil
+}
+
+// apply copies the set fields of req onto s
+func (req *SubscriptionRequest) apply(s *Subscription) {
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.URL != nil {
+		s.URL = strings.TrimSpace(*req.URL)
+	}
+	if req.Secret != nil {
+		s.Secret = *req.Secret
+	}
+	if req.EventTypes != nil {
+		s.EventTypes = req.EventTypes
+	}
+	if req.ProjectIDs != nil {
+		s.ProjectIDs = *req.ProjectIDs
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+}
+
+// newSecret generates a signing secret
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Create adds a subscription. The returned subscription carries its secret;
+// it is not shown again.
+func (m *Manager) Create(req SubscriptionRequest, createdBy string) (*Subscription, error) {
+	now := time.Now().UTC()
+	s := &Subscription{
+		ID:        uuid.New().String(),
+		Enabled:   true,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req.apply(s)
+	if s.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return nil, err
+		}
+		s.Secret = secret
+	}
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	if s.Name == "" {
+		if u, err := url.Parse(s.URL); err == nil {
+			s.Name = u.Host
+		}
+	}
+	if err := m.store.SaveEventWebhook(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update changes the fields set in req
+func (m *Manager) Update(id string, req SubscriptionRequest) (*Subscription, error) {
+	s, err := m.subscription(id)
+	if err != nil {
+		return nil, err
+	}
+	req.apply(s)
+	if err := s.validate(); err != nil {
+		return nil, err
+	}
+	s.UpdatedAt = time.Now().UTC()
+	if err := m.store.SaveEventWebhook(s); err != nil {
+		return nil, err
+	}
+	return s.redacted(), nil
+}
+
+// Get returns a subscription without its secret
+func (m *Manager) Get(id string) (*Subscription, error) {
+	s, err := m.subscription(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.redacted(), nil
+}
+
+// List returns every subscription without its secret
+func (m *Manager) List() ([]*Subscription, error) {
+	list, err := m.store.ListEventWebhooks()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Subscription, 0, len(list))
+	for _, s := range list {
+		out = append(out, s.redacted())
+	}
+	return out, nil
+}
+
+// Delete removes a subscription and its delivery log
+func (m *Manager) Delete(id string) error {
+	if _, err := m.subscription(id); err != nil {
+		return err
+	}
+	return m.store.DeleteEventWebhook(id)
+}
+
+// Deliveries returns a page of a subscription's delivery log
+func (m *Manager) Deliveries(f DeliveryFilter) ([]*Delivery, int, error) {
+	if _, err := m.subscription(f.SubscriptionID); err != nil {
+		return nil, 0, err
+	}
+	return m.store.ListWebhookDeliveries(f)
+}
+
+func (m *Manager) subscription(id string) (*Subscription, error) {
+	s, err := m.store.GetEventWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("event webhook %s not found", id)
+	}
+	return s, nil
+}
+
+// Publish queues ev for every subscription that matches it. Deliveries are
+// attempted in the background.
+func (m *Manager) Publish(ev Event) {
+	if m == nil {
+		return
+	}
+	subs, err := m.store.ListEventWebhooks()
+	if err != nil {
+		log.Printf("[EventWebhooks] Failed to list subscriptions: %v", err)
+		return
+	}
+	for _, s := range subs {
+		if !s.Matches(ev) {
+			continue
+		}
+		d, err := m.enqueue(s.ID, ev)
+		if err != nil {
+			log.Printf("[EventWebhooks] Failed to queue %s for %s: %v", ev.Type, s.ID, err)
+			continue
+		}
+		go m.attempt(d.ID)
+	}
+}
+
+// Test sends a ping event to a subscription, whatever its filters, and
+// returns the outcome of the first attempt
+func (m *Manager) Test(id string) (*Delivery, error) {
+	s, err := m.subscription(id)
+	if err != nil {
+		return nil, err
+	}
+	d, err := m.enqueue(s.ID, Event{
+		ID:        uuid.New().String(),
+		Type:      EventPing,
+		Timestamp: time.Now().UTC(),
+		Data:      map[string]interface{}{"subscription_id": s.ID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m.attempt(d.ID), nil
+}
+
+// Redeliver sends the payload of an earlier delivery again as a new
+// delivery and returns the outcome of its first attempt
+func (m *Manager) Redeliver(subscriptionID, deliveryID string) (*Delivery, error) {
+	prev, err := m.store.GetWebhookDelivery(deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if prev == nil || prev.SubscriptionID != subscriptionID {
+		return nil, fmt.Errorf("delivery %s not found", deliveryID)
+	}
+	now := time.Now().UTC()
+	d := &Delivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: prev.SubscriptionID,
+		EventID:        prev.EventID,
+		EventType:      prev.EventType,
+		ProjectID:      prev.ProjectID,
+		Payload:        prev.Payload,
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := m.store.SaveWebhookDelivery(d); err != nil {
+		return nil, err
+	}
+	return m.attempt(d.ID), nil
+}
+
+// Resume schedules the retries of deliveries left pending by a restart
+func (m *Manager) Resume() error {
+	if m == nil {
+		return nil
+	}
+	pending, _, err := m.store.ListWebhookDeliveries(DeliveryFilter{Status: StatusPending, Ascending: true})
+	if err != nil {
+		return err
+	}
+	for _, d := range pending {
+		var delay time.Duration
+		if d.NextAttemptAt != nil {
+			delay = time.Until(*d.NextAttemptAt)
+		}
+		m.schedule(d.ID, delay)
+	}
+	return nil
+}
+
+// Close cancels scheduled retries; they stay pending for Resume
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	for id, t := range m.timers {
+		t.Stop()
+		delete(m.timers, id)
+	}
+}
+
+// enqueue records a pending delivery of ev to a subscription
+func (m *Manager) enqueue(subscriptionID string, ev Event) (*Delivery, error) {
+	payload, err := json.Marshal(ev)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	now := time.Now().UTC()
+	d := &Delivery{
+		ID:             uuid.New().String(),
+		SubscriptionID: subscriptionID,
+		EventID:        ev.ID,
+		EventType:      ev.Type,
+		ProjectID:      ev.ProjectID,
+		Payload:        string(payload),
+		Status:         StatusPending,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	if err := m.store.SaveWebhookDelivery(d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// attempt makes the next attempt of a pending delivery, scheduling a retry
+// if it fails and attempts remain
+func (m *Manager) attempt(id string) *Delivery {
+	d, err := m.store.GetWebhookDelivery(id)
+	if err != nil || d == nil || d.Status != StatusPending {
+		return d
+	}
+	s, err := m.store.GetEventWebhook(d.SubscriptionID)
+	if err != nil {
+		log.Printf("[EventWebhooks] Failed to load subscription %s: %v", d.SubscriptionID, err)
+		m.schedule(d.ID, m.backoff(d.Attempts+1))
+		return d
+	}
+	if s == nil {
+		// The subscription and its log are gone
+		return d
+	}
+
+	d.Attempts++
+	d.NextAttemptAt = nil
+	d.UpdatedAt = time.Now().UTC()
+	var code int
+	if !s.Enabled && d.EventType != EventPing {
+		err = fmt.Errorf("subscription is disabled")
+	} else {
+		code, err = m.send(s, d)
+	}
+	d.ResponseCode = code
+
+	switch {
+	case err == nil:
+		d.Status = StatusDelivered
+		d.Error = ""
+	case d.Attempts >= m.cfg.MaxAttempts || !s.Enabled || permanentFailure(code):
+		d.Status = StatusFailed
+		d.Error = err.Error()
+	default:
+		d.Error = err.Error()
+		delay := m.backoff(d.Attempts)
+		next := d.UpdatedAt.Add(delay)
+		d.NextAttemptAt = &next
+	}
+	if err := m.store.SaveWebhookDelivery(d); err != nil {
+		log.Printf("[EventWebhooks] Failed to record delivery %s: %v", d.ID, err)
+	}
+	if d.NextAttemptAt != nil {
+		m.schedule(d.ID, time.Until(*d.NextAttemptAt))
+	}
+	return d
+}
+
+// send POSTs a delivery's payload, returning the response status code
+func (m *Manager) send(s *Subscription, d *Delivery) (int, error) {
+	body := []byte(d.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Loom-Webhooks/1.0")
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderDelivery, d.ID)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(s.Secret, timestamp, body))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// permanentFailure reports whether a response means retrying is pointless:
+// client errors other than timeouts and rate limiting
+func permanentFailure(code int) bool {
+	return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+}
+
+// backoff returns the wait after the given number of failed attempts
+func (m *Manager) backoff(attempts int) time.Duration {
+	delay := m.cfg.InitialBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if m.cfg.MaxBackoff > 0 && delay >= m.cfg.MaxBackoff {
+			return m.cfg.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// schedule attempts a delivery again after delay
+func (m *Manager) schedule(id string, delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	if t, ok := m.timers[id]; ok {
+		t.Stop()
+	}
+	m.timers[id] = time.AfterFunc(delay, func() {
+		m.mu.Lock()
+		delete(m.timers, id)
+		m.mu.Unlock()
+		m.attempt(id)
+	})
+}
@@ -0,0 +1,186 @@
+package eventhooks_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "eventhooks.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestMatches(t *testing.T) {
+	s := &eventhooks.Subscription{
+		Enabled:    true,
+		EventTypes: []string{"bead.closed", "workflow.*"},
+		ProjectIDs: []string{"proj-1"},
+	}
+	cases := []struct {
+		ev   eventhooks.Event
+		want bool
+	}{
+		{eventhooks.Event{Type: "bead.completed", ProjectID: "proj-1"}, true},
+		{eventhooks.Event{Type: "workflow.failed", ProjectID: "proj-1"}, true},
+		{eventhooks.Event{Type: "bead.created", ProjectID: "proj-1"}, false},
+		{eventhooks.Event{Type: "bead.completed", ProjectID: "proj-2"}, false},
+	}
+	for _, c := range cases {
+		if got := s.Matches(c.ev); got != c.want {
+			t.Errorf("Matches(%s in %s) = %v, want %v", c.ev.Type, c.ev.ProjectID, got, c.want)
+		}
+	}
+
+	s.Enabled = false
+	if s.Matches(eventhooks.Event{Type: "bead.completed", ProjectID: "proj-1"}) {
+		t.Error("expected a disabled subscription to match nothing")
+	}
+}
+
+func TestPublishSignsAndLogsDelivery(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	bodies := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- r
+		bodies <- body
+	}))
+	defer srv.Close()
+
+	m := eventhooks.NewManager(newTestDB(t), eventhooks.DefaultConfig())
+	defer m.Close()
+	sub, err := m.Create(eventhooks.SubscriptionRequest{URL: strPtr(srv.URL), EventTypes: []string{"bead.closed"}}, "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if sub.Secret == "" {
+		t.Fatal("expected a generated secret")
+	}
+
+	m.Publish(eventhooks.Event{ID: "ev-1", Type: "bead.created"})
+	m.Publish(eventhooks.Event{ID: "ev-2", Type: "bead.completed", ProjectID: "proj-1"})
+
+	var r *http.Request
+	var body []byte
+	select {
+	case r = <-received:
+		body = <-bodies
+	case <-time.After(5 * time.Second):
+		t.Fatal("delivery not received")
+	}
+	if r.Header.Get(eventhooks.HeaderEvent) != "bead.completed" {
+		t.Errorf("unexpected event header %q", r.Header.Get(eventhooks.HeaderEvent))
+	}
+	if !eventhooks.Verify(sub.Secret, r.Header.Get(eventhooks.HeaderTimestamp), body, r.Header.Get(eventhooks.HeaderSignature)) {
+		t.Error("signature did not verify")
+	}
+
+	var list []*eventhooks.Delivery
+	for i := 0; i < 50; i++ {
+		list, _, _ = m.Deliveries(eventhooks.DeliveryFilter{SubscriptionID: sub.ID})
+		if len(list) == 1 && list[0].Status == eventhooks.StatusDelivered {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(list) != 1 || list[0].Status != eventhooks.StatusDelivered || list[0].EventID != "ev-2" || list[0].ResponseCode != 200 {
+		t.Fatalf("expected one delivered ev-2, got %+v", list)
+	}
+
+	got, err := m.Get(sub.ID)
+	if err != nil || got.Secret != "" {
+		t.Errorf("expected Get to hide the secret, got %+v (%v)", got, err)
+	}
+}
+
+func TestRetriesUntilDelivered(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	m := eventhooks.NewManager(newTestDB(t), eventhooks.Config{MaxAttempts: 5, InitialBackoff: 10 * time.Millisecond})
+	defer m.Close()
+	sub, err := m.Create(eventhooks.SubscriptionRequest{URL: strPtr(srv.URL), EventTypes: []string{"*"}}, "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	d, err := m.Test(sub.ID)
+	if err != nil {
+		t.Fatalf("Test failed: %v", err)
+	}
+	if d.Status != eventhooks.StatusPending || d.ResponseCode != http.StatusServiceUnavailable || d.NextAttemptAt == nil {
+		t.Fatalf("expected a scheduled retry after the first attempt, got %+v", d)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		list, _, _ := m.Deliveries(eventhooks.DeliveryFilter{SubscriptionID: sub.ID})
+		if len(list) == 1 && list[0].Status == eventhooks.StatusDelivered {
+			if list[0].Attempts != 3 {
+				t.Errorf("expected 3 attempts, got %d", list[0].Attempts)
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("delivery was not retried to success")
+}
+
+func TestClientErrorFailsWithoutRetry(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer srv.Close()
+
+	m := eventhooks.NewManager(newTestDB(t), eventhooks.Config{MaxAttempts: 5, InitialBackoff: time.Millisecond})
+	defer m.Close()
+	sub, err := m.Create(eventhooks.SubscriptionRequest{URL: strPtr(srv.URL), EventTypes: []string{"*"}}, "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	d, err := m.Test(sub.ID)
+	if err != nil {
+		t.Fatalf("Test failed: %v", err)
+	}
+	if d.Status != eventhooks.StatusFailed || d.Attempts != 1 {
+		t.Fatalf("expected failure after one attempt, got %+v", d)
+	}
+
+	again, err := m.Redeliver(sub.ID, d.ID)
+	if err != nil {
+		t.Fatalf("Redeliver failed: %v", err)
+	}
+	if again.ID == d.ID || again.Payload != d.Payload {
+		t.Errorf("expected a new delivery of the same payload, got %+v", again)
+	}
+}
+
+func TestCreateValidates(t *testing.T) {
+	m := eventhooks.NewManager(newTestDB(t), eventhooks.DefaultConfig())
+	if _, err := m.Create(eventhooks.SubscriptionRequest{URL: strPtr("ftp://example.com"), EventTypes: []string{"*"}}, ""); err == nil {
+		t.Error("expected non-http URL to be rejected")
+	}
+	if _, err := m.Create(eventhooks.SubscriptionRequest{URL: strPtr("https://example.com/hook")}, ""); err == nil {
+		t.Error("expected missing event types to be rejected")
+	}
+}
@@ -0,0 +1,216 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Sandbox modes
+const (
+	SandboxModeLocal  = "local"
+	SandboxModeDocker = "docker"
+	SandboxModePodman = "podman"
+)
+
+const (
+	defaultSandboxImage     = "golang:1.24"
+	defaultSandboxNetwork   = "none"
+	defaultSandboxMountPath = "/workspace"
+)
+
+// SandboxConfig describes how agent commands are isolated for a project
+type SandboxConfig struct {
+	Mode      string            `json:"mode"`                 // local, docker or podman
+	Image     string            `json:"image,omitempty"`      // Container image
+	CPUs      string            `json:"cpus,omitempty"`       // e.g. "2" or "0.5"
+	Memory    string            `json:"memory,omitempty"`     // e.g. "2g"
+	Network   string            `json:"network,omitempty"`    // none, bridge or host
+	MountPath string            `json:"mount_path,omitempty"` // Where the worktree is mounted in the container
+	ReadOnly  bool              `json:"read_only,omitempty"`  // Mount the worktree read-only
+	Env       map[string]string `json:"env,omitempty"`
+
+	// FallbackLocal runs commands on the host when the container runtime
+	// is not installed instead of failing them.
+	FallbackLocal bool `json:"fallback_local"`
+}
+
+// merge returns c with any fields set in override applied on top
+func (c SandboxConfig) merge(override SandboxConfig) SandboxConfig {
+	if override.Mode != "" {
+		c.Mode = override.Mode
+	}
+	if override.Image != "" {
+		c.Image = override.Image
+	}
+	if override.CPUs != "" {
+		c.CPUs = override.CPUs
+	}
+	if override.Memory != "" {
+		c.Memory = override.Memory
+	}
+	if override.Network != "" {
+		c.Network = override.Network
+	}
+	if override.MountPath != "" {
+		c.MountPath = override.MountPath
+	}
+	if override.ReadOnly {
+		c.ReadOnly = true
+	}
+	if len(override.Env) > 0 {
+		env := make(map[string]string, len(c.Env)+len(override.Env))
+		for k, v := range c.Env {
+			env[k] = v
+		}
+		for k, v := range override.Env {
+			env[k] = v
+		}
+		c.Env = env
+	}
+	return c
+}
+
+// Validate checks the sandbox mode and network policy
+func (c SandboxConfig) Validate() error {
+	switch c.Mode {
+	case "", SandboxModeLocal, SandboxModeDocker, SandboxModePodman:
+	default:
+		return fmt.Errorf("invalid sandbox mode %q (expected local, docker or podman)", c.Mode)
+	}
+	switch c.Network {
+	case "", "none", "bridge", "host":
+	default:
+		return fmt.Errorf("invalid sandbox network %q (expected none, bridge or host)", c.Network)
+	}
+	return nil
+}
+
+// SandboxPolicy resolves the sandbox configuration for each project
+type SandboxPolicy struct {
+	mu       sync.RWMutex
+	defaults SandboxConfig
+	projects map[string]SandboxConfig
+
+	// lookPath is swapped out in tests
+	lookPath func(file string) (string, error)
+}
+
+// NewSandboxPolicy creates a policy with the given defaults
+func NewSandboxPolicy(defaults SandboxConfig) *SandboxPolicy {
+	return &SandboxPolicy{
+		defaults: defaults,
+		projects: make(map[string]SandboxConfig),
+		lookPath: exec.LookPath,
+	}
+}
+
+// SetProjectConfig overrides the defaults for a single project
+func (p *SandboxPolicy) SetProjectConfig(projectID string, cfg SandboxConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.projects[projectID] = cfg
+	return nil
+}
+
+// ForProject returns the effective sandbox configuration for a project
+func (p *SandboxPolicy) ForProject(projectID string) SandboxConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	cfg := p.defaults
+	if override, ok := p.projects[projectID]; ok {
+		cfg = cfg.merge(override)
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = SandboxModeLocal
+	}
+	if cfg.Mode != SandboxModeLocal {
+		if cfg.Image == "" {
+			cfg.Image = defaultSandboxImage
+		}
+		if cfg.Network == "" {
+			cfg.Network = defaultSandboxNetwork
+		}
+		if cfg.MountPath == "" {
+			cfg.MountPath = defaultSandboxMountPath
+		}
+	}
+	return cfg
+}
+
+// Command builds the exec.Cmd for a command under the project's sandbox.
+// args is either a single shell string (useShell) or an argv. The returned
+// mode is the mode actually used, which differs from the configured mode
+// when falling back to local execution.
+func (p *SandboxPolicy) Command(ctx context.Context, projectID, workDir string, args []string, useShell bool) (*exec.Cmd, string, error) {
+	if len(args) == 0 {
+		return nil, "", fmt.Errorf("empty command")
+	}
+	cfg := p.ForProject(projectID)
+
+	if cfg.Mode != SandboxModeLocal {
+		runtime, err := p.lookPath(cfg.Mode)
+		if err == nil {
+			return exec.CommandContext(ctx, runtime, containerArgs(cfg, workDir, args, useShell)...), cfg.Mode, nil
+		}
+		if !cfg.FallbackLocal {
+			return nil, "", fmt.Errorf("sandbox runtime %s not available: %w", cfg.Mode, err)
+		}
+		log.Printf("[Sandbox] %s not available for project %s, falling back to local execution", cfg.Mode, projectID)
+	}
+
+	var cmd *exec.Cmd
+	if useShell {
+		cmd = exec.CommandContext(ctx, "/bin/sh", "-c", args[0])
+	} else {
+		cmd = exec.CommandContext(ctx, args[0], args[1:]...)
+	}
+	cmd.Dir = workDir
+	return cmd, SandboxModeLocal, nil
+}
+
+// containerArgs builds the `run` arguments for docker/podman. Both runtimes
+// accept the same flags for everything used here.
+func containerArgs(cfg SandboxConfig, workDir string, args []string, useShell bool) []string {
+	if abs, err := filepath.Abs(workDir); err == nil {
+		workDir = abs
+	}
+	mount := fmt.Sprintf("%s:%s", workDir, cfg.MountPath)
+	if cfg.ReadOnly {
+		mount += ":ro"
+	}
+
+	run := []string{"run", "--rm", "-i",
+		"--network", cfg.Network,
+		"-v", mount,
+		"-w", cfg.MountPath,
+	}
+	if cfg.CPUs != "" {
+		run = append(run, "--cpus", cfg.CPUs)
+	}
+	if cfg.Memory != "" {
+		run = append(run, "--memory", cfg.Memory)
+	}
+	keys := make([]string, 0, len(cfg.Env))
+	for k := range cfg.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		run = append(run, "-e", fmt.Sprintf("%s=%s", k, cfg.Env[k]))
+	}
+	run = append(run, cfg.Image)
+
+	if useShell {
+		return append(run, "/bin/sh", "-c", args[0])
+	}
+	return append(run, args...)
+}
@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func newTestPolicy(defaults SandboxConfig, runtimeInstalled bool) *SandboxPolicy {
+	p := NewSandboxPolicy(defaults)
+	p.lookPath = func(file string) (string, error) {
+		if runtimeInstalled {
+			return "/usr/bin/" + file, nil
+		}
+		return "", errors.New("not found")
+	}
+	return p
+}
+
+func TestSandboxPolicy_ForProject(t *testing.T) {
+	p := newTestPolicy(SandboxConfig{Mode: SandboxModeDocker, Memory: "2g", Env: map[string]string{"A": "1"}}, true)
+	if err := p.SetProjectConfig("proj-1", SandboxConfig{Image: "node:20", Memory: "4g", Env: map[string]string{"B": "2"}}); err != nil {
+		t.Fatalf("SetProjectConfig failed: %v", err)
+	}
+
+	cfg := p.ForProject("proj-1")
+	if cfg.Mode != SandboxModeDocker || cfg.Image != "node:20" || cfg.Memory != "4g" {
+		t.Errorf("override not applied: %+v", cfg)
+	}
+	if cfg.Env["A"] != "1" || cfg.Env["B"] != "2" {
+		t.Errorf("env not merged: %v", cfg.Env)
+	}
+	if cfg.Network != "none" || cfg.MountPath != "/workspace" {
+		t.Errorf("expected isolated defaults, got %+v", cfg)
+	}
+
+	if other := p.ForProject("proj-2"); other.Image != defaultSandboxImage {
+		t.Errorf("expected default image for other project, got %q", other.Image)
+	}
+
+	if err := p.SetProjectConfig("proj-3", SandboxConfig{Mode: "vm"}); err == nil {
+		t.Error("expected invalid mode to be rejected")
+	}
+}
+
+func TestSandboxPolicy_ContainerCommand(t *testing.T) {
+	p := newTestPolicy(SandboxConfig{Mode: SandboxModePodman, Image: "golang:1.24", CPUs: "2", Memory: "1g", ReadOnly: true}, true)
+
+	cmd, mode, err := p.Command(context.Background(), "proj-1", "/src/proj", []string{"go", "test", "./..."}, false)
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if mode != SandboxModePodman {
+		t.Errorf("mode = %q, want podman", mode)
+	}
+	want := []string{"/usr/bin/podman", "run", "--rm", "-i",
+		"--network", "none",
+		"-v", "/src/proj:/workspace:ro",
+		"-w", "/workspace",
+		"--cpus", "2",
+		"--memory", "1g",
+		"golang:1.24", "go", "test", "./..."}
+	if !reflect.DeepEqual(cmd.Args, want) {
+		t.Errorf("args = %v\nwant %v", cmd.Args, want)
+	}
+
+	cmd, _, err = p.Command(context.Background(), "proj-1", "/src/proj", []string{"go test ./... | tail -5"}, true)
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	tail := cmd.Args[len(cmd.Args)-3:]
+	if !reflect.DeepEqual(tail, []string{"/bin/sh", "-c", "go test ./... | tail -5"}) {
+		t.Errorf("shell command not wrapped: %v", cmd.Args)
+	}
+}
+
+func TestSandboxPolicy_LocalFallback(t *testing.T) {
+	p := newTestPolicy(SandboxConfig{Mode: SandboxModeDocker, FallbackLocal: true}, false)
+	cmd, mode, err := p.Command(context.Background(), "proj-1", "/tmp", []string{"ls", "-la"}, false)
+	if err != nil {
+		t.Fatalf("Command failed: %v", err)
+	}
+	if mode != SandboxModeLocal || cmd.Dir != "/tmp" {
+		t.Errorf("expected local fallback in /tmp, got mode=%q dir=%q", mode, cmd.Dir)
+	}
+
+	strict := newTestPolicy(SandboxConfig{Mode: SandboxModeDocker}, false)
+	if _, _, err := strict.Command(context.Background(), "proj-1", "/tmp", []string{"ls"}, false); err == nil {
+		t.Error("expected error when runtime is missing and fallback disabled")
+	}
+}
@@ -39,6 +39,11 @@ var allowedCommands = map[string]bool{
 	"mocha":    true,
 	"go test":  true, // Special case handled in parsing
 
+	// Static analysis
+	"gosec":       true,
+	"staticcheck": true,
+	"eslint":      true,
+
 	// Common utilities (read-only operations)
 	"ls":   true,
 	"cat":  true,
@@ -69,16 +74,30 @@ var allowedCommands = map[string]bool{
 
 // ShellExecutor provides shell command execution with persistent logging
 type ShellExecutor struct {
-	db *sql.DB
+	db      *sql.DB
+	sandbox *SandboxPolicy
 }
 
 // NewShellExecutor creates a new shell executor
 func NewShellExecutor(db *sql.DB) *ShellExecutor {
 	return &ShellExecutor{
-		db: db,
+		db:      db,
+		sandbox: NewSandboxPolicy(SandboxConfig{Mode: SandboxModeLocal}),
 	}
 }
 
+// SetSandboxPolicy sets the policy used to isolate commands per project
+func (e *ShellExecutor) SetSandboxPolicy(policy *SandboxPolicy) {
+	if policy != nil {
+		e.sandbox = policy
+	}
+}
+
+// GetSandboxPolicy returns the sandbox policy
+func (e *ShellExecutor) GetSandboxPolicy() *SandboxPolicy {
+	return e.sandbox
+}
+
 // validateCommand checks if a command is allowed and returns the parsed command parts
 func validateCommand(command string) ([]string, bool, error) {
 	// Empty command check
@@ -195,17 +214,17 @@ func (e *ShellExecutor) ExecuteCommand(ctx context.Context, req ExecuteCommandRe
 	// Execute command
 	log.Printf("[ShellExecutor] Executing command for agent=%s bead=%s: %s", req.AgentID, req.BeadID, req.Command)
 
-	var cmd *exec.Cmd
-	if requiresShell {
-		// Complex command requires shell interpretation (piping, redirection, etc.)
-		log.Printf("[ShellExecutor] Using shell for complex command")
-		cmd = exec.CommandContext(cmdCtx, "/bin/sh", "-c", parts[0])
-	} else {
-		// Simple command - execute directly without shell for security
-		log.Printf("[ShellExecutor] Direct execution (no shell)")
-		cmd = exec.CommandContext(cmdCtx, parts[0], parts[1:]...)
+	// Complex commands require shell interpretation (piping, redirection, etc.);
+	// simple commands execute directly without a shell for security
+	cmd, sandboxMode, err := e.sandbox.Command(cmdCtx, req.ProjectID, workingDir, parts, requiresShell)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox setup failed: %w", err)
+	}
+	log.Printf("[ShellExecutor] Executing in %s sandbox (shell=%v)", sandboxMode, requiresShell)
+	if cmdLog.Context == nil {
+		cmdLog.Context = make(map[string]interface{})
 	}
-	cmd.Dir = workingDir
+	cmdLog.Context["sandbox"] = sandboxMode
 
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
@@ -4,12 +4,15 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+
+	"github.com/jordanhubbard/loom/internal/patch"
 )
 
 const (
@@ -48,6 +51,10 @@ type SearchMatch struct {
 type PatchResult struct {
 	Applied bool   `json:"applied"`
 	Output  string `json:"output,omitempty"`
+	// Strategy is "git" when git applied the patch as it was, or "fuzzy"
+	// when the patch engine had to re-anchor it
+	Strategy  string           `json:"strategy,omitempty"`
+	Conflicts []patch.Conflict `json:"conflicts,omitempty"` // Hunks that did not apply
 }
 
 type WriteResult struct {
@@ -291,13 +298,13 @@ func extractPatchFiles(patch string) ([]string, error) {
 	return files, nil
 }
 
-func (m *Manager) ApplyPatch(ctx context.Context, projectID, patch string) (*PatchResult, error) {
-	if strings.TrimSpace(patch) == "" {
+func (m *Manager) ApplyPatch(ctx context.Context, projectID, text string) (*PatchResult, error) {
+	if strings.TrimSpace(text) == "" {
 		return nil, fmt.Errorf("patch is required")
 	}
 
 	// Validate patch size (prevent DoS)
-	if len(patch) > 10*1024*1024 { // 10MB limit
+	if len(text) > 10*1024*1024 { // 10MB limit
 		return nil, fmt.Errorf("patch too large (max 10MB)")
 	}
 
@@ -307,59 +314,168 @@ func (m *Manager) ApplyPatch(ctx context.Context, projectID, patch string) (*Pat
 	}
 
 	// Extract and validate all files in the patch
-	files, err := extractPatchFiles(patch)
-	if err != nil {
+	format := patch.DetectFormat(text)
+	var files []string
+	if format == patch.FormatSearchReplace {
+		parsed, parseErr := patch.Parse(text)
+		if parseErr != nil {
+			return nil, fmt.Errorf("invalid patch format: %w", parseErr)
+		}
+		for _, fp := range parsed {
+			files = append(files, fp.Path)
+		}
+	} else if files, err = extractPatchFiles(text); err != nil {
 		return nil, fmt.Errorf("invalid patch format: %w", err)
 	}
 
 	// Validate each file path
 	for _, file := range files {
-		// Use safeJoin to validate path is within project
-		fullPath, err := safeJoin(workDir, file)
-		if err != nil {
-			return nil, fmt.Errorf("patch modifies unauthorized file: %s (%w)", file, err)
-		}
-
-		// Check if path is blocked (e.g., .git, .env)
-		if isBlockedPath(fullPath) {
-			return nil, fmt.Errorf("patch modifies blocked file: %s", file)
+		if err := validatePatchFile(workDir, file); err != nil {
+			return nil, err
 		}
+	}
 
-		// Additional sensitive file checks
-		lowercaseFile := strings.ToLower(file)
-		sensitivePatterns := []string{".env", "secret", "password", "key", "token", "credentials"}
-		for _, pattern := range sensitivePatterns {
-			if strings.Contains(lowercaseFile, pattern) {
-				return nil, fmt.Errorf("patch modifies potentially sensitive file: %s", file)
-			}
-		}
+	// Search/replace blocks and diffs that git cannot apply as they are
+	// go to the fuzzy patch engine
+	if format == patch.FormatSearchReplace {
+		return applyWithEngine(workDir, text, "")
 	}
 
 	// First, check if patch is valid without applying it
 	checkCmd := exec.CommandContext(ctx, "git", "apply", "--check", "--whitespace=nowarn", "-")
 	checkCmd.Dir = workDir
-	checkCmd.Stdin = strings.NewReader(patch)
+	checkCmd.Stdin = strings.NewReader(text)
 	var checkOut bytes.Buffer
 	checkCmd.Stdout = &checkOut
 	checkCmd.Stderr = &checkOut
 	if err := checkCmd.Run(); err != nil {
-		return &PatchResult{
-			Applied: false,
-			Output:  fmt.Sprintf("patch validation failed: %s", strings.TrimSpace(checkOut.String())),
-		}, fmt.Errorf("patch validation failed: %w", err)
+		return applyWithEngine(workDir, text, strings.TrimSpace(checkOut.String()))
 	}
 
 	// Now apply the patch
 	cmd := exec.CommandContext(ctx, "git", "apply", "--whitespace=nowarn", "--recount", "-")
 	cmd.Dir = workDir
-	cmd.Stdin = strings.NewReader(patch)
+	cmd.Stdin = strings.NewReader(text)
 	var out bytes.Buffer
 	cmd.Stdout = &out
 	cmd.Stderr = &out
 	if err := cmd.Run(); err != nil {
 		return &PatchResult{Applied: false, Output: strings.TrimSpace(out.String())}, err
 	}
-	return &PatchResult{Applied: true, Output: strings.TrimSpace(out.String())}, nil
+	return &PatchResult{Applied: true, Output: strings.TrimSpace(out.String()), Strategy: "git"}, nil
+}
+
+// validatePatchFile refuses a patch that touches a file outside the
+// project, a blocked path or a file that may hold secrets
+func validatePatchFile(workDir, file string) error {
+	// Use safeJoin to validate path is within project
+	fullPath, err := safeJoin(workDir, file)
+	if err != nil {
+		return fmt.Errorf("patch modifies unauthorized file: %s (%w)", file, err)
+	}
+
+	// Check if path is blocked (e.g., .git, .env)
+	if isBlockedPath(fullPath) {
+		return fmt.Errorf("patch modifies blocked file: %s", file)
+	}
+
+	// Additional sensitive file checks
+	lowercaseFile := strings.ToLower(file)
+	sensitivePatterns := []string{".env", "secret", "password", "key", "token", "credentials"}
+	for _, pattern := range sensitivePatterns {
+		if strings.Contains(lowercaseFile, pattern) {
+			return fmt.Errorf("patch modifies potentially sensitive file: %s", file)
+		}
+	}
+	return nil
+}
+
+// applyWithEngine applies a patch with the fuzzy patch engine. Every file
+// is patched in memory first, so a conflict in one file leaves all of them
+// untouched. gitOutput is why git refused the patch, if it did.
+func applyWithEngine(workDir, text, gitOutput string) (*PatchResult, error) {
+	parsed, err := patch.Parse(text)
+	if err != nil {
+		return &PatchResult{Applied: false, Output: gitOutput}, fmt.Errorf("patch validation failed: %w", err)
+	}
+
+	type change struct {
+		target  string
+		content string
+		delete  bool
+	}
+	var changes []change
+	var conflicts []patch.Conflict
+	var placed []string
+	for _, fp := range parsed {
+		if err := validatePatchFile(workDir, fp.Path); err != nil {
+			return nil, err
+		}
+		target, _ := safeJoin(workDir, fp.Path)
+		data, readErr := os.ReadFile(target)
+		switch {
+		case readErr == nil && fp.Create && len(data) > 0:
+			conflicts = append(conflicts, patch.Conflict{Path: fp.Path, Hunk: 1, Reason: "the patch creates the file but it already exists"})
+			continue
+		case readErr != nil && !(os.IsNotExist(readErr) && fp.Create):
+			conflicts = append(conflicts, patch.Conflict{Path: fp.Path, Hunk: 1, Reason: fmt.Sprintf("cannot read the file: %v", readErr)})
+			continue
+		}
+		if fp.Delete {
+			changes = append(changes, change{target: target, delete: true})
+			placed = append(placed, fmt.Sprintf("%s: deleted", fp.Path))
+			continue
+		}
+		content, placements, applyErr := patch.Apply(string(data), fp)
+		var conflictErr *patch.ConflictError
+		if errors.As(applyErr, &conflictErr) {
+			conflicts = append(conflicts, conflictErr.Conflicts...)
+			continue
+		} else if applyErr != nil {
+			return nil, applyErr
+		}
+		changes = append(changes, change{target: target, content: content})
+		for _, p := range placements {
+			placed = append(placed, fmt.Sprintf("%s: hunk %d at line %d (%s)", fp.Path, p.Hunk, p.Line, p.Strategy))
+		}
+	}
+	if len(conflicts) > 0 {
+		return &PatchResult{Applied: false, Conflicts: conflicts}, &patch.ConflictError{Conflicts: conflicts}
+	}
+
+	// Write every file, restoring the ones already written if one fails
+	originals := make(map[string][]byte)
+	rollback := func() {
+		for target, data := range originals {
+			if data == nil {
+				os.Remove(target)
+			} else {
+				_ = os.WriteFile(target, data, 0644)
+			}
+		}
+	}
+	for _, c := range changes {
+		data, err := os.ReadFile(c.target)
+		if err == nil {
+			originals[c.target] = data
+		} else {
+			originals[c.target] = nil
+		}
+		if c.delete {
+			err = os.Remove(c.target)
+		} else if err = os.MkdirAll(filepath.Dir(c.target), 0755); err == nil {
+			mode := os.FileMode(0644)
+			if info, statErr := os.Stat(c.target); statErr == nil {
+				mode = info.Mode().Perm()
+			}
+			err = os.WriteFile(c.target, []byte(c.content), mode)
+		}
+		if err != nil {
+			rollback()
+			return &PatchResult{Applied: false}, fmt.Errorf("failed to write patched file: %w", err)
+		}
+	}
+	return &PatchResult{Applied: true, Output: strings.Join(placed, "\n"), Strategy: "fuzzy"}, nil
 }
 
 func (m *Manager) WriteFile(ctx context.Context, projectID, relPath, content string) (*WriteResult, error) {
@@ -1017,6 +1017,55 @@ func TestApplyPatch_InvalidPatchFormat(t *testing.T) {
 	}
 }
 
+func TestApplyPatch_FuzzyFallback(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "calc.go"), []byte("package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mgr := NewManager(staticResolver{dir: dir})
+
+	// Wrong line numbers and spaces for tabs: git refuses, the engine re-anchors
+	patch := "--- a/calc.go\n+++ b/calc.go\n@@ -10,3 +10,3 @@\n func Add(a, b int) int {\n-    return a + b\n+\treturn b + a\n }\n"
+	res, err := mgr.ApplyPatch(context.Background(), "proj-1", patch)
+	if err != nil {
+		t.Fatalf("ApplyPatch failed: %v", err)
+	}
+	if !res.Applied || res.Strategy != "fuzzy" || !strings.Contains(res.Output, "calc.go: hunk 1 at line 3 (whitespace)") {
+		t.Errorf("result = %+v", res)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "calc.go"))
+	if !strings.Contains(string(data), "\treturn b + a\n") {
+		t.Errorf("calc.go = %q", data)
+	}
+
+	// Search/replace blocks never go through git
+	res, err = mgr.ApplyPatch(context.Background(), "proj-1", "calc.go\n<<<<<<< SEARCH\n\treturn b + a\n=======\n\treturn a + b\n>>>>>>> REPLACE\n")
+	if err != nil || !res.Applied || res.Strategy != "fuzzy" {
+		t.Errorf("search/replace = %+v, %v", res, err)
+	}
+}
+
+func TestApplyPatch_ConflictLeavesFilesUntouched(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "one\ntwo\n", "b.txt": "three\nfour\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mgr := NewManager(staticResolver{dir: dir})
+	patch := "a.txt\n<<<<<<< SEARCH\none\n=======\nONE\n>>>>>>> REPLACE\nb.txt\n<<<<<<< SEARCH\nfive\n=======\nFIVE\n>>>>>>> REPLACE\n"
+	res, err := mgr.ApplyPatch(context.Background(), "proj-1", patch)
+	if err == nil || !strings.Contains(err.Error(), "b.txt hunk 1") {
+		t.Fatalf("expected a conflict in b.txt, got %v", err)
+	}
+	if res == nil || res.Applied || len(res.Conflicts) != 1 || res.Conflicts[0].Path != "b.txt" {
+		t.Errorf("result = %+v", res)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "a.txt")); string(data) != "one\ntwo\n" {
+		t.Errorf("a.txt was changed: %q", data)
+	}
+}
+
 // --- helper functions ---
 
 func TestSafeJoin(t *testing.T) {
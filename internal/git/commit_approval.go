@@ -0,0 +1,99 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// CommitGate holds agent commits for approval. The staged diff of a commit
+// is proposed as a patch, and once the patch is approved a commit of the
+// same diff goes through.
+type CommitGate interface {
+	// ReviewCommit decides whether a staged patch may be committed. When
+	// it may not, it returns the ID of the patch awaiting review and, if
+	// the patch was rejected, the reviewer's reason.
+	ReviewCommit(projectID string, patch *StagedPatch) (approved bool, patchID, rejection string)
+}
+
+// StagedPatch is the diff a commit is about to record
+type StagedPatch struct {
+	BeadID      string
+	AgentID     string
+	Message     string
+	Files       []string
+	Diff        string
+	Fingerprint string // Hash of the diff
+}
+
+// CommitHeldError is returned when a commit's patch awaits approval or was
+// rejected. The changes stay staged.
+type CommitHeldError struct {
+	PatchID   string
+	Files     []string
+	Rejection string // The reviewer's reason, when the patch was rejected
+}
+
+func (e *CommitHeldError) Error() string {
+	if e.Rejection != "" {
+		return fmt.Sprintf("patch %s of these changes was rejected: %s; rework the changes before committing", e.PatchID, e.Rejection)
+	}
+	return fmt.Sprintf("commit held for approval as patch %s (%s); commit the same changes again once it is approved",
+		e.PatchID, strings.Join(e.Files, ", "))
+}
+
+// SetCommitGate configures the approval hook for commits. Without a gate,
+// commits are not held.
+func (s *GitService) SetCommitGate(gate CommitGate) {
+	s.commitGate = gate
+}
+
+// applyCommitGate proposes the staged changes to the gate and returns a
+// CommitHeldError unless they are approved
+func (s *GitService) applyCommitGate(ctx context.Context, req CommitRequest) error {
+	if s.commitGate == nil {
+		return nil
+	}
+	patch, err := s.stagedPatch(ctx, req)
+	if err != nil {
+		return err
+	}
+	if patch.Diff == "" {
+		return nil
+	}
+	approved, patchID, rejection := s.commitGate.ReviewCommit(s.projectID, patch)
+	if approved {
+		s.auditLogger.LogOperation("commit_approved_patch", req.BeadID, patch.Fingerprint, true, nil)
+		return nil
+	}
+	return &CommitHeldError{PatchID: patchID, Files: patch.Files, Rejection: rejection}
+}
+
+// stagedPatch reads the staged diff and the files it touches
+func (s *GitService) stagedPatch(ctx context.Context, req CommitRequest) (*StagedPatch, error) {
+	cmd := exec.CommandContext(ctx, "git", "diff", "--cached", "--no-color", "--no-ext-diff")
+	cmd.Dir = s.projectPath
+	diff, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get staged diff: %w", err)
+	}
+	cmd = exec.CommandContext(ctx, "git", "diff", "--cached", "--name-only")
+	cmd.Dir = s.projectPath
+	names, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list staged files: %w", err)
+	}
+
+	sum := sha256.Sum256(diff)
+	return &StagedPatch{
+		BeadID:      req.BeadID,
+		AgentID:     req.AgentID,
+		Message:     req.Message,
+		Files:       strings.Fields(string(names)),
+		Diff:        string(diff),
+		Fingerprint: hex.EncodeToString(sum[:]),
+	}, nil
+}
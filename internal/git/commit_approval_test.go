@@ -0,0 +1,55 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type fakeCommitGate struct {
+	approved map[string]bool
+	proposed []*StagedPatch
+}
+
+func (g *fakeCommitGate) ReviewCommit(_ string, patch *StagedPatch) (bool, string, string) {
+	if g.approved[patch.Fingerprint] {
+		return true, "", ""
+	}
+	g.proposed = append(g.proposed, patch)
+	return false, "patch-1", ""
+}
+
+func TestGitServiceCommitHeldForApproval(t *testing.T) {
+	dir, cleanup := setupTestGitRepo(t)
+	defer cleanup()
+
+	svc := createTestGitService(t, dir)
+	gate := &fakeCommitGate{approved: map[string]bool{}}
+	svc.SetCommitGate(gate)
+	ctx := context.Background()
+
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("# Notes\n"), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	req := CommitRequest{BeadID: "bead-1", AgentID: "agent-1", Message: "Add notes", Files: []string{"notes.md"}}
+	_, err := svc.Commit(ctx, req)
+	var held *CommitHeldError
+	if !errors.As(err, &held) {
+		t.Fatalf("expected CommitHeldError, got %v", err)
+	}
+	if held.PatchID != "patch-1" || len(gate.proposed) != 1 {
+		t.Fatalf("patch was not proposed: %+v", gate.proposed)
+	}
+	patch := gate.proposed[0]
+	if len(patch.Files) != 1 || patch.Files[0] != "notes.md" || !strings.Contains(patch.Diff, "+# Notes") || patch.Fingerprint == "" {
+		t.Errorf("unexpected staged patch %+v", patch)
+	}
+
+	gate.approved[patch.Fingerprint] = true
+	if _, err := svc.Commit(ctx, req); err != nil {
+		t.Fatalf("commit should succeed once the patch is approved: %v", err)
+	}
+}
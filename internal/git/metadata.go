@@ -19,6 +19,7 @@ type CommitMetadata struct {
 	Progress  map[string]int `json:"progress,omitempty"`
 	Subject   string         `json:"subject"`
 	Timestamp time.Time      `json:"timestamp"`
+	Files     []string       `json:"files,omitempty"` // Set by LoomCommits
 }
 
 // ParseCommitMetadata extracts Loom metadata from a commit message body.
@@ -104,6 +105,44 @@ func (s *GitService) GetBeadCommits(ctx context.Context, beadID string) ([]Commi
 	return commits, nil
 }
 
+// LoomCommits returns up to maxCount of the most recent commits on any
+// branch that carry a Bead trailer, newest first, with the files each one
+// changed
+func (s *GitService) LoomCommits(ctx context.Context, maxCount int) ([]CommitMetadata, error) {
+	if maxCount <= 0 {
+		maxCount = 200
+	}
+	// Each commit starts with a record separator; the message ends with a
+	// unit separator and the changed files follow, one per line
+	output, err := s.git(ctx, "log", "--all", fmt.Sprintf("--max-count=%d", maxCount),
+		"--fixed-strings", "--grep=Bead: ", "--name-only", "--format=%x1e%H|%aI|%B%x1f")
+	if err != nil {
+		return nil, fmt.Errorf("git log failed: %w", err)
+	}
+
+	var commits []CommitMetadata
+	for _, entry := range strings.Split(output, "\x1e") {
+		header, files, _ := strings.Cut(entry, "\x1f")
+		parts := strings.SplitN(strings.TrimSpace(header), "|", 3)
+		if len(parts) < 3 {
+			continue
+		}
+		meta := ParseCommitMetadata(parts[2])
+		if meta.BeadID == "" {
+			continue
+		}
+		meta.SHA = parts[0]
+		meta.Timestamp, _ = time.Parse(time.RFC3339, parts[1])
+		for _, f := range strings.Split(files, "\n") {
+			if f = strings.TrimSpace(f); f != "" {
+				meta.Files = append(meta.Files, f)
+			}
+		}
+		commits = append(commits, *meta)
+	}
+	return commits, nil
+}
+
 // extractTrailer extracts the value for a trailer key from a line.
 // Returns empty string if the line does not match.
 func extractTrailer(line, key string) string {
@@ -1,6 +1,10 @@
 package git
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -322,3 +326,49 @@ func TestGitServiceStruct(t *testing.T) {
 		t.Errorf("branchPrefix: expected agent/, got %s", svc.branchPrefix)
 	}
 }
+
+func TestLoomCommits(t *testing.T) {
+	dir, cleanup := setupTestGitRepo(t)
+	defer cleanup()
+	svc := createTestGitService(t, dir)
+	ctx := context.Background()
+
+	if err := os.MkdirAll(filepath.Join(dir, "pkg", "provider"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"pkg/provider/client.go", "pkg/provider/retry.go"} {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte("package provider\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := execGit(dir, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "commit", "-m", "Retry provider calls\n\nBead: bd-7\nAgent: agent-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("no bead\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "add", "notes.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "commit", "-m", "Untracked work"); err != nil {
+		t.Fatal(err)
+	}
+
+	commits, err := svc.LoomCommits(ctx, 0)
+	if err != nil {
+		t.Fatalf("LoomCommits failed: %v", err)
+	}
+	if len(commits) != 1 {
+		t.Fatalf("expected only the commit with a bead trailer, got %+v", commits)
+	}
+	c := commits[0]
+	if c.BeadID != "bd-7" || c.AgentID != "agent-1" || c.SHA == "" || c.Timestamp.IsZero() {
+		t.Errorf("unexpected metadata %+v", c)
+	}
+	if strings.Join(c.Files, ",") != "pkg/provider/client.go,pkg/provider/retry.go" {
+		t.Errorf("unexpected files %v", c.Files)
+	}
+}
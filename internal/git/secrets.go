@@ -0,0 +1,319 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// High-entropy detection thresholds. Random base64 of this length scores
+// well above 4.5 bits per char; hex digests top out at 4.0 and are skipped.
+const (
+	minEntropyTokenLen = 32
+	minTokenEntropy    = 4.5
+)
+
+// secretRule is a named pattern the scanner blocks on
+type secretRule struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+var (
+	secretRules = []secretRule{
+		// Assignments of long alphanumeric values to key-like names
+		{"api_key", regexp.MustCompile(`(?i)api[_-]?key[_-]?[:=]\s*['"][a-zA-Z0-9]{20,}['"]`)},
+		{"secret_key", regexp.MustCompile(`(?i)secret[_-]?key[_-]?[:=]\s*['"][a-zA-Z0-9]{20,}['"]`)},
+		{"token", regexp.MustCompile(`(?i)token[_-]?[:=]\s*['"][a-zA-Z0-9]{20,}['"]`)},
+		// Provider key formats
+		{"aws_access_key_id", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{"github_token", regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{50,})\b`)},
+		{"openai_api_key", regexp.MustCompile(`\bsk-(proj-|ant-)?[A-Za-z0-9_-]{32,}`)},
+		{"google_api_key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+		{"slack_token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+		// Private key blocks
+		{"private_key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )*PRIVATE KEY( BLOCK)?-----`)},
+		// Note: password patterns removed — too many false positives on default/placeholder
+		// passwords in source code (e.g., "loom-default-password"). Real password leaks are
+		// better caught by the API key and token patterns above.
+	}
+
+	entropyTokenPattern = regexp.MustCompile(`[A-Za-z0-9+/_=-]{32,}`)
+
+	// Lockfiles and checksum manifests are full of high-entropy hashes
+	secretScanSkipFiles = map[string]bool{
+		"go.sum":            true,
+		"package-lock.json": true,
+		"yarn.lock":         true,
+		"pnpm-lock.yaml":    true,
+		"Cargo.lock":        true,
+		"poetry.lock":       true,
+	}
+)
+
+// SecretFinding is a potential secret in an added line
+type SecretFinding struct {
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Rule     string `json:"rule"`
+	Redacted string `json:"redacted"` // The match with all but its first four characters masked
+	hash     string
+}
+
+// SecretsBlockedError is returned when the secrets scanner blocks a commit
+// or pull request. Fingerprint identifies the exact set of findings so an
+// approved override applies only to them.
+type SecretsBlockedError struct {
+	Operation   string          `json:"operation"`
+	Findings    []SecretFinding `json:"findings"`
+	Fingerprint string          `json:"fingerprint"`
+	DecisionID  string          `json:"decision_id,omitempty"` // Pending override decision, if one was requested
+}
+
+func (e *SecretsBlockedError) Error() string {
+	locations := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		locations = append(locations, fmt.Sprintf("%s:%d (%s)", f.File, f.Line, f.Rule))
+	}
+	msg := fmt.Sprintf("potential secret detected in %s", strings.Join(locations, ", "))
+	if e.DecisionID != "" {
+		msg += fmt.Sprintf("; override requires CEO approval of decision %s", e.DecisionID)
+	}
+	return msg
+}
+
+// SecretsGate decides whether blocked secrets may be committed anyway and
+// is told about every block, so it can record lessons and request overrides.
+type SecretsGate interface {
+	// OverrideApproved reports whether findings with this fingerprint have
+	// been approved for the project
+	OverrideApproved(projectID, fingerprint string) bool
+	// SecretsBlocked is called when an operation is blocked and returns the
+	// ID of the decision that can override it, if any
+	SecretsBlocked(projectID, beadID, agentID string, blocked *SecretsBlockedError) string
+}
+
+// SetSecretsGate configures the override and reporting hook for the
+// secrets scanner. Without a gate, findings always block.
+func (s *GitService) SetSecretsGate(gate SecretsGate) {
+	s.secretsGate = gate
+}
+
+// ScanDiff scans the added lines of a unified diff for potential secrets.
+// Hunk lengths are tracked so an added line that reads "++ x" is scanned as
+// content, not taken for the "+++ " header of the next file.
+func ScanDiff(diff string) []SecretFinding {
+	var findings []SecretFinding
+	var file string
+	line := 0
+	oldLeft, newLeft := 0, 0 // Lines of the current hunk still to come
+	afterOldHeader := false
+
+	scanner := bufio.NewScanner(strings.NewReader(diff))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		inHunk := oldLeft > 0 || newLeft > 0
+		isNewHeader := afterOldHeader && strings.HasPrefix(text, "+++ ")
+		afterOldHeader = false
+		switch {
+		case isNewHeader:
+			file = strings.TrimPrefix(strings.TrimPrefix(text, "+++ "), "b/")
+		case !inHunk && strings.HasPrefix(text, "--- "):
+			afterOldHeader = true
+		case !inHunk && strings.HasPrefix(text, "@@"):
+			line = hunkStartLine(text)
+			oldLeft, newLeft = hunkLengths(text)
+		case inHunk && strings.HasPrefix(text, "+"):
+			if file != "/dev/null" && !secretScanSkipFiles[filepath.Base(file)] {
+				findings = append(findings, scanLine(file, line, text[1:])...)
+			}
+			line++
+			newLeft--
+		case inHunk && strings.HasPrefix(text, "-"):
+			oldLeft--
+		case inHunk && strings.HasPrefix(text, " "):
+			line++
+			oldLeft--
+			newLeft--
+		}
+	}
+	return findings
+}
+
+// scanLine checks one line against the rules and the entropy heuristic
+func scanLine(file string, line int, text string) []SecretFinding {
+	var findings []SecretFinding
+	matched := false
+	for _, rule := range secretRules {
+		if m := rule.pattern.FindString(text); m != "" {
+			findings = append(findings, newSecretFinding(file, line, rule.name, m))
+			matched = true
+		}
+	}
+	if matched {
+		return findings
+	}
+
+	for _, token := range entropyTokenPattern.FindAllString(text, -1) {
+		if isHighEntropyToken(token) {
+			findings = append(findings, newSecretFinding(file, line, "high_entropy_string", token))
+		}
+	}
+	return findings
+}
+
+// isHighEntropyToken flags long random-looking tokens. Requiring mixed case
+// and digits keeps identifiers, paths and hex digests out.
+func isHighEntropyToken(token string) bool {
+	if len(token) < minEntropyTokenLen {
+		return false
+	}
+	var upper, lower, digit bool
+	for _, r := range token {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= '0' && r <= '9':
+			digit = true
+		}
+	}
+	if !upper || !lower || !digit {
+		return false
+	}
+	return shannonEntropy(token) >= minTokenEntropy
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, r := range s {
+		counts[r]++
+	}
+	n := float64(len([]rune(s)))
+	entropy := 0.0
+	for _, c := range counts {
+		p := float64(c) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+func newSecretFinding(file string, line int, rule, match string) SecretFinding {
+	sum := sha256.Sum256([]byte(match))
+	return SecretFinding{
+		File:     file,
+		Line:     line,
+		Rule:     rule,
+		Redacted: redactSecret(match),
+		hash:     hex.EncodeToString(sum[:]),
+	}
+}
+
+func redactSecret(s string) string {
+	if len(s) <= 4 {
+		return strings.Repeat("*", len(s))
+	}
+	return s[:4] + strings.Repeat("*", len(s)-4)
+}
+
+// secretsFingerprint identifies a set of findings by file, rule and matched
+// value, independent of line numbers so rebasing does not invalidate an
+// approved override
+func secretsFingerprint(findings []SecretFinding) string {
+	keys := make([]string, 0, len(findings))
+	for _, f := range findings {
+		keys = append(keys, f.File+"|"+f.Rule+"|"+f.hash)
+	}
+	sort.Strings(keys)
+	sum := sha256.Sum256([]byte(strings.Join(keys, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// hunkStartLine parses the new-file start line from "@@ -a,b +c,d @@"
+func hunkStartLine(header string) int {
+	fields := strings.Fields(header)
+	for _, f := range fields {
+		if strings.HasPrefix(f, "+") {
+			start := strings.SplitN(strings.TrimPrefix(f, "+"), ",", 2)[0]
+			if n, err := strconv.Atoi(start); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// hunkLengths parses the old and new line counts from "@@ -a,b +c,d @@";
+// an omitted count is 1
+func hunkLengths(header string) (int, int) {
+	oldLen, newLen := 0, 0
+	for _, f := range strings.Fields(header) {
+		if !strings.HasPrefix(f, "-") && !strings.HasPrefix(f, "+") {
+			continue
+		}
+		n := 1
+		if parts := strings.SplitN(f[1:], ",", 2); len(parts) == 2 {
+			v, err := strconv.Atoi(parts[1])
+			if err != nil {
+				continue
+			}
+			n = v
+		}
+		if f[0] == '-' {
+			oldLen = n
+		} else {
+			newLen = n
+		}
+	}
+	return oldLen, newLen
+}
+
+// scanDiffForSecrets runs git diff with args and scans the added lines
+func (s *GitService) scanDiffForSecrets(ctx context.Context, operation string, args ...string) error {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"diff", "--no-color", "--no-ext-diff", "-U0"}, args...)...)
+	cmd.Dir = s.projectPath
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to get diff for secret scan: %w", err)
+	}
+
+	findings := ScanDiff(string(output))
+	if len(findings) == 0 {
+		return nil
+	}
+	return &SecretsBlockedError{
+		Operation:   operation,
+		Findings:    findings,
+		Fingerprint: secretsFingerprint(findings),
+	}
+}
+
+// applySecretsGate lets an approved override through and otherwise reports
+// the block to the gate. Errors other than SecretsBlockedError pass through.
+func (s *GitService) applySecretsGate(scanErr error, beadID, agentID string) error {
+	blocked, ok := scanErr.(*SecretsBlockedError)
+	if !ok || s.secretsGate == nil {
+		return scanErr
+	}
+	if s.secretsGate.OverrideApproved(s.projectID, blocked.Fingerprint) {
+		s.auditLogger.LogOperation(blocked.Operation+"_secrets_override", beadID, blocked.Fingerprint, true, nil)
+		return nil
+	}
+	blocked.DecisionID = s.secretsGate.SecretsBlocked(s.projectID, beadID, agentID, blocked)
+	return blocked
+}
@@ -4,14 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
 	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/logging"
 )
 
+// gitLog is the structured logger of agent git operations
+var gitLog = logging.Logger("git")
+
 // GitService provides safe git operations for agents
 type GitService struct {
 	projectPath   string
@@ -19,6 +26,8 @@ type GitService struct {
 	projectKeyDir string // Base directory for per-project SSH keys
 	branchPrefix  string // Configurable branch prefix (default: "agent/")
 	auditLogger   *AuditLogger
+	secretsGate   SecretsGate // Optional override/reporting hook for the secrets scanner
+	commitGate    CommitGate  // Optional approval hook holding commits as proposed patches
 }
 
 // NewGitService creates a new git service instance.
@@ -153,11 +162,17 @@ func (s *GitService) Commit(ctx context.Context, req CommitRequest) (*CommitResu
 	}
 
 	// Check for secrets
-	if err := s.checkForSecrets(ctx); err != nil {
+	if err := s.applySecretsGate(s.checkForSecrets(ctx), req.BeadID, req.AgentID); err != nil {
 		s.auditLogger.LogOperation("commit", req.BeadID, "", false, err)
 		return nil, fmt.Errorf("secret detected: %w", err)
 	}
 
+	// Hold the commit until its patch is approved, if the project says so
+	if err := s.applyCommitGate(ctx, req); err != nil {
+		s.auditLogger.LogOperation("commit", req.BeadID, "", false, err)
+		return nil, err
+	}
+
 	// Create commit
 	cmd := exec.CommandContext(ctx, "git", "commit", "-m", req.Message)
 	cmd.Dir = s.projectPath
@@ -347,34 +362,19 @@ func (s *GitService) stageFiles(ctx context.Context, files []string, allowAll bo
 	return nil
 }
 
-// checkForSecrets scans staged files for potential secrets
+// checkForSecrets scans the lines added by staged changes for potential secrets
 func (s *GitService) checkForSecrets(ctx context.Context) error {
-	// Get list of staged files
-	cmd := exec.CommandContext(ctx, "git", "diff", "--staged", "--name-only")
-	cmd.Dir = s.projectPath
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to get staged files: %w", err)
-	}
-
-	files := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, file := range files {
-		if file == "" {
-			continue
-		}
-
-		filePath := filepath.Join(s.projectPath, file)
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			continue // Skip files that can't be read
-		}
+	return s.scanDiffForSecrets(ctx, "commit", "--staged")
+}
 
-		if hasSecrets(content) {
-			return fmt.Errorf("potential secret detected in %s", file)
-		}
+// checkBranchForSecrets scans the lines a branch adds relative to base,
+// preferring the remote base so unpushed local history is not skipped
+func (s *GitService) checkBranchForSecrets(ctx context.Context, base, branch string) error {
+	err := s.scanDiffForSecrets(ctx, "create_pr", "origin/"+base+"..."+branch)
+	if _, blocked := err.(*SecretsBlockedError); err == nil || blocked {
+		return err
 	}
-
-	return nil
+	return s.scanDiffForSecrets(ctx, "create_pr", base+"..."+branch)
 }
 
 // getLastCommitSHA returns the SHA of the last commit
@@ -515,20 +515,6 @@ var (
 		"^release/.*",
 		"^hotfix/.*",
 	}
-
-	secretPatterns = []*regexp.Regexp{
-		// Match high-entropy API keys (20+ alphanumeric chars with mixed case/digits)
-		regexp.MustCompile(`(?i)api[_-]?key[_-]?[:=]\s*['"][a-zA-Z0-9]{20,}['"]`),
-		regexp.MustCompile(`(?i)secret[_-]?key[_-]?[:=]\s*['"][a-zA-Z0-9]{20,}['"]`),
-		regexp.MustCompile(`(?i)token[_-]?[:=]\s*['"][a-zA-Z0-9]{20,}['"]`),
-		// AWS access key IDs have a known format
-		regexp.MustCompile(`(?i)aws[_-]?access[_-]?key[_-]?id\s*[:=]\s*['"]AKIA[0-9A-Z]{16}['"]`),
-		// Private key blocks
-		regexp.MustCompile(`-----BEGIN (RSA|DSA|EC|OPENSSH) PRIVATE KEY-----`),
-		// Note: password patterns removed — too many false positives on default/placeholder
-		// passwords in source code (e.g., "loom-default-password"). Real password leaks are
-		// better caught by the API key and token patterns above.
-	}
 )
 
 // validateBranchNameWithPrefix validates branch name with a configurable prefix
@@ -591,8 +577,8 @@ func isProtectedBranch(branchName string) bool {
 
 // hasSecrets checks if content contains potential secrets
 func hasSecrets(content []byte) bool {
-	for _, pattern := range secretPatterns {
-		if pattern.Match(content) {
+	for _, rule := range secretRules {
+		if rule.pattern.Match(content) {
 			return true
 		}
 	}
@@ -629,6 +615,16 @@ func isGitRepo(path string) bool {
 	return err == nil && info.IsDir()
 }
 
+// auditedGitOperations are forwarded to the central audit log because they
+// publish or rewrite shared history
+var auditedGitOperations = map[string]bool{
+	"push":      true,
+	"create_pr": true,
+	"merge":     true,
+	"revert":    true,
+	"close_pr":  true,
+}
+
 // AuditLogger logs git operations for security audit
 type AuditLogger struct {
 	projectID string
@@ -671,6 +667,34 @@ func (l *AuditLogger) LogOperationWithDuration(operation, beadID, ref string, su
 		entry["error"] = err.Error()
 	}
 
+	level := slog.LevelInfo
+	args := []any{"operation", operation, "project_id", l.projectID, "bead_id", beadID, "ref", ref,
+		"success", success, "duration_ms", duration.Milliseconds()}
+	if err != nil {
+		level = slog.LevelWarn
+		args = append(args, "error", err)
+	}
+	gitLog.Log(context.Background(), level, "git operation", args...)
+
+	if auditedGitOperations[operation] {
+		outcome := audit.OutcomeSuccess
+		if !success {
+			outcome = audit.OutcomeFailure
+		}
+		details := map[string]interface{}{"bead_id": beadID}
+		if err != nil {
+			details["error"] = err.Error()
+		}
+		audit.Record(audit.Event{
+			Actor:     "git",
+			Action:    "git." + operation,
+			Resource:  ref,
+			ProjectID: l.projectID,
+			Outcome:   outcome,
+			Details:   details,
+		})
+	}
+
 	// Write to log file
 	data, _ := json.Marshal(entry)
 	f, err := os.OpenFile(l.logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -747,6 +771,12 @@ func (s *GitService) CreatePR(ctx context.Context, req CreatePRRequest) (*Create
 		return nil, resultErr
 	}
 
+	// Scan everything the PR would introduce, not just the latest commit
+	if err := s.applySecretsGate(s.checkBranchForSecrets(ctx, base, branch), req.BeadID, ""); err != nil {
+		resultErr = fmt.Errorf("secret detected: %w", err)
+		return nil, resultErr
+	}
+
 	// Build gh pr create command
 	args := []string{"pr", "create"}
 	args = append(args, "--base", base)
@@ -0,0 +1,260 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// WorktreeSnapshot records the state of a project's working copy before a
+// dispatch so everything the dispatch changed can later be rolled back
+type WorktreeSnapshot struct {
+	Branch   string            `json:"branch"`             // Checked-out branch
+	HeadSHA  string            `json:"head_sha"`           // Commit HEAD pointed at
+	TreeSHA  string            `json:"tree_sha,omitempty"` // Tree of the working copy, set only when it had uncommitted changes
+	Branches map[string]string `json:"branches"`           // Local branch name -> tip SHA
+}
+
+// Snapshot captures the current branch, HEAD, local branch tips and, when
+// the working copy is dirty, a tree object holding its files (untracked
+// ones included, ignored ones not). Nothing in the repository is modified.
+func (s *GitService) Snapshot(ctx context.Context) (*WorktreeSnapshot, error) {
+	branch, err := s.getCurrentBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := s.getLastCommitSHA(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	snap := &WorktreeSnapshot{
+		Branch:   branch,
+		HeadSHA:  head,
+		Branches: make(map[string]string),
+	}
+
+	output, err := s.git(ctx, "for-each-ref", "--format=%(refname:short) %(objectname)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if name, sha, ok := strings.Cut(strings.TrimSpace(line), " "); ok {
+			snap.Branches[name] = sha
+		}
+	}
+
+	status, err := s.git(ctx, "status", "--porcelain")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check working tree: %w", err)
+	}
+	if status != "" {
+		tree, err := s.writeWorktreeTree(ctx)
+		if err != nil {
+			return nil, err
+		}
+		snap.TreeSHA = tree
+	}
+
+	return snap, nil
+}
+
+// writeWorktreeTree stores the working copy as a tree object using a
+// throwaway index, leaving the real index untouched
+func (s *GitService) writeWorktreeTree(ctx context.Context) (string, error) {
+	indexFile, err := os.CreateTemp("", "loom-snapshot-index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create snapshot index: %w", err)
+	}
+	indexPath := indexFile.Name()
+	indexFile.Close()
+	os.Remove(indexPath)
+	defer os.Remove(indexPath)
+
+	env := append(os.Environ(), "GIT_INDEX_FILE="+indexPath)
+	for _, args := range [][]string{{"read-tree", "HEAD"}, {"add", "-A"}} {
+		cmd := exec.CommandContext(ctx, "git", args...)
+		cmd.Dir = s.projectPath
+		cmd.Env = env
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("git %s failed: %w\nOutput: %s", args[0], err, output)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "write-tree")
+	cmd.Dir = s.projectPath
+	cmd.Env = env
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git write-tree failed: %w\nOutput: %s", err, output)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// DiffSince returns a patch of everything that changed in the working copy
+// since the snapshot, committed or not, including untracked files
+func (s *GitService) DiffSince(ctx context.Context, snap *WorktreeSnapshot) (string, error) {
+	base := snap.TreeSHA
+	if base == "" {
+		base = snap.HeadSHA
+	}
+	current, err := s.writeWorktreeTree(ctx)
+	if err != nil {
+		return "", err
+	}
+	output, err := s.git(ctx, "diff", "--binary", base, current)
+	if err != nil {
+		return "", fmt.Errorf("failed to diff working copy: %w", err)
+	}
+	if output != "" {
+		output += "\n"
+	}
+	return output, nil
+}
+
+// NewBranchesSince returns the local branches that did not exist when the
+// snapshot was taken and whose name contains the bead ID
+func (s *GitService) NewBranchesSince(ctx context.Context, snap *WorktreeSnapshot, beadID string) ([]string, error) {
+	output, err := s.git(ctx, "for-each-ref", "--format=%(refname:short)", "refs/heads")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches: %w", err)
+	}
+
+	var branches []string
+	for _, name := range strings.Split(output, "\n") {
+		name = strings.TrimSpace(name)
+		if name == "" || !strings.Contains(name, beadID) {
+			continue
+		}
+		if _, existed := snap.Branches[name]; !existed {
+			branches = append(branches, name)
+		}
+	}
+	return branches, nil
+}
+
+// BeadCommitsSince returns the commits on local branches that were not
+// reachable from any branch when the snapshot was taken and that carry
+// the bead's trailer, newest first
+func (s *GitService) BeadCommitsSince(ctx context.Context, snap *WorktreeSnapshot, beadID string) ([]string, error) {
+	args := []string{"rev-list", "--branches", "--fixed-strings", "--grep=Bead: " + beadID}
+	if snap.HeadSHA != "" {
+		args = append(args, "^"+snap.HeadSHA)
+	}
+	for _, sha := range snap.Branches {
+		args = append(args, "^"+sha)
+	}
+
+	output, err := s.git(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("git rev-list failed: %w", err)
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+// IsAncestor reports whether commit is reachable from ref
+func (s *GitService) IsAncestor(ctx context.Context, commit, ref string) (bool, error) {
+	cmd := exec.CommandContext(ctx, "git", "merge-base", "--is-ancestor", commit, ref)
+	cmd.Dir = s.projectPath
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("git merge-base failed for %s: %w", commit, err)
+	}
+	return true, nil
+}
+
+// HasUpstream reports whether the current branch tracks a remote branch
+func (s *GitService) HasUpstream(ctx context.Context) bool {
+	_, err := s.git(ctx, "rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{u}")
+	return err == nil
+}
+
+// DiscardChanges throws away uncommitted changes and untracked files
+func (s *GitService) DiscardChanges(ctx context.Context, beadID string) error {
+	for _, args := range [][]string{{"reset", "--hard", "HEAD"}, {"clean", "-fd"}} {
+		if _, err := s.git(ctx, args...); err != nil {
+			s.auditLogger.LogOperation("discard_changes", beadID, "", false, err)
+			return fmt.Errorf("failed to discard changes: %w", err)
+		}
+	}
+	s.auditLogger.LogOperation("discard_changes", beadID, "", true, nil)
+	return nil
+}
+
+// RestoreWorktree discards uncommitted changes, then writes back the
+// working copy files recorded in the snapshot, if any. The index is left
+// matching HEAD.
+func (s *GitService) RestoreWorktree(ctx context.Context, snap *WorktreeSnapshot, beadID string) error {
+	startTime := time.Now()
+
+	if err := s.DiscardChanges(ctx, beadID); err != nil {
+		return err
+	}
+	if snap.TreeSHA == "" {
+		return nil
+	}
+	for _, args := range [][]string{{"checkout", snap.TreeSHA, "--", "."}, {"reset", "-q", "HEAD"}} {
+		if _, err := s.git(ctx, args...); err != nil {
+			s.auditLogger.LogOperation("restore_worktree", beadID, snap.TreeSHA, false, err)
+			return fmt.Errorf("failed to restore working copy: %w", err)
+		}
+	}
+
+	s.auditLogger.LogOperationWithDuration("restore_worktree", beadID, snap.TreeSHA, true, nil, time.Since(startTime))
+	return nil
+}
+
+// ClosePRRequest defines parameters for closing a pull request
+type ClosePRRequest struct {
+	Number  int    // PR number
+	BeadID  string // Bead ID for audit trail
+	Comment string // Optional comment left on the PR
+}
+
+// ClosePR closes a pull request without merging it, using gh CLI
+func (s *GitService) ClosePR(ctx context.Context, req ClosePRRequest) error {
+	startTime := time.Now()
+	ref := fmt.Sprintf("#%d", req.Number)
+
+	if !isGhCLIAvailable() {
+		err := fmt.Errorf("gh CLI not found (install from https://cli.github.com)")
+		s.auditLogger.LogOperation("close_pr", req.BeadID, ref, false, err)
+		return err
+	}
+
+	args := []string{"pr", "close", fmt.Sprintf("%d", req.Number)}
+	if req.Comment != "" {
+		args = append(args, "--comment", req.Comment)
+	}
+	cmd := exec.CommandContext(ctx, "gh", args...)
+	cmd.Dir = s.projectPath
+	if output, err := cmd.CombinedOutput(); err != nil {
+		err = fmt.Errorf("gh pr close failed: %w\nOutput: %s", err, output)
+		s.auditLogger.LogOperation("close_pr", req.BeadID, ref, false, err)
+		return err
+	}
+
+	s.auditLogger.LogOperationWithDuration("close_pr", req.BeadID, ref, true, nil, time.Since(startTime))
+	return nil
+}
+
+// git runs a git command in the project and returns its trimmed stdout
+func (s *GitService) git(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = s.projectPath
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
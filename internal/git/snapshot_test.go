@@ -0,0 +1,115 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotAndRestore(t *testing.T) {
+	dir, cleanup := setupTestGitRepo(t)
+	defer cleanup()
+	svc := createTestGitService(t, dir)
+	ctx := context.Background()
+
+	// Uncommitted work from before the dispatch must survive the rollback
+	readme := filepath.Join(dir, "README.md")
+	if err := os.WriteFile(readme, []byte("# Test Repo\nlocal edit\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("draft\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	snap, err := svc.Snapshot(ctx)
+	if err != nil {
+		t.Fatalf("Snapshot failed: %v", err)
+	}
+	if snap.TreeSHA == "" || snap.HeadSHA == "" || snap.Branches[snap.Branch] != snap.HeadSHA {
+		t.Fatalf("unexpected snapshot: %+v", snap)
+	}
+	if status, _ := svc.GetStatus(ctx); status == "" {
+		t.Fatal("Snapshot must not touch the working copy")
+	}
+
+	// The dispatch commits on the original branch and on a new agent branch
+	if err := execGit(dir, "add", "-A"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "commit", "-m", "Work on base\n\nBead: bd-9"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "checkout", "-b", "agent/bd-9/fix"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fix.go"), []byte("package fix\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "add", "fix.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "commit", "-m", "Fix\n\nBead: bd-9"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "scratch.txt"), []byte("tmp\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	branches, err := svc.NewBranchesSince(ctx, snap, "bd-9")
+	if err != nil || len(branches) != 1 || branches[0] != "agent/bd-9/fix" {
+		t.Fatalf("expected the agent branch, got %v (%v)", branches, err)
+	}
+	commits, err := svc.BeadCommitsSince(ctx, snap, "bd-9")
+	if err != nil || len(commits) != 2 {
+		t.Fatalf("expected 2 dispatch commits, got %v (%v)", commits, err)
+	}
+	if other, _ := svc.BeadCommitsSince(ctx, snap, "bd-10"); len(other) != 0 {
+		t.Errorf("commits of other beads should not match, got %v", other)
+	}
+
+	diff, err := svc.DiffSince(ctx, snap)
+	if err != nil {
+		t.Fatalf("DiffSince failed: %v", err)
+	}
+	if !strings.Contains(diff, "fix.go") || !strings.Contains(diff, "scratch.txt") || strings.Contains(diff, "notes.txt") {
+		t.Errorf("expected diff of the dispatch's changes only, got:\n%s", diff)
+	}
+
+	// Roll back: drop the branch, revert the base commit, restore files
+	if err := svc.DiscardChanges(ctx, "bd-9"); err != nil {
+		t.Fatalf("DiscardChanges failed: %v", err)
+	}
+	if _, err := svc.Checkout(ctx, CheckoutRequest{Branch: snap.Branch}); err != nil {
+		t.Fatalf("Checkout failed: %v", err)
+	}
+	if _, err := svc.DeleteBranch(ctx, DeleteBranchRequest{Branch: "agent/bd-9/fix"}); err != nil {
+		t.Fatalf("DeleteBranch failed: %v", err)
+	}
+	onBase, err := svc.IsAncestor(ctx, commits[1], "HEAD")
+	if err != nil || !onBase {
+		t.Fatalf("expected the base commit on HEAD: %v", err)
+	}
+	if _, err := svc.Revert(ctx, RevertRequest{CommitSHAs: []string{commits[1]}, BeadID: "bd-9"}); err != nil {
+		t.Fatalf("Revert failed: %v", err)
+	}
+	if err := svc.RestoreWorktree(ctx, snap, "bd-9"); err != nil {
+		t.Fatalf("RestoreWorktree failed: %v", err)
+	}
+
+	if content, _ := os.ReadFile(readme); string(content) != "# Test Repo\nlocal edit\n" {
+		t.Errorf("README not restored, got %q", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dir, "notes.txt")); string(content) != "draft\n" {
+		t.Errorf("untracked file not restored, got %q", content)
+	}
+	for _, name := range []string{"fix.go", "scratch.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s should be gone after rollback", name)
+		}
+	}
+	if diff, _ := svc.GetDiff(ctx, true); diff != "" {
+		t.Errorf("index should match HEAD after restore, got staged diff %q", diff)
+	}
+}
@@ -0,0 +1,141 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UndoBeadRequest defines parameters for undoing everything a bead did
+type UndoBeadRequest struct {
+	BeadID    string   // Bead whose work is undone
+	Branches  []string // Branches the bead pushed; its local agent branches are found as well
+	PRNumbers []int    // Pull requests the bead opened
+	Base      string   // Branch kept, and switched to when a bead branch is checked out (default: main)
+	Reason    string   // Reason left on closed PRs and revert commits
+}
+
+// UndoBeadResult reports what undoing a bead changed
+type UndoBeadResult struct {
+	Branch          string   `json:"branch"` // Branch the reverts landed on
+	ClosedPRs       []int    `json:"closed_prs"`
+	DeletedBranches []string `json:"deleted_branches"`
+	RevertedCommits []string `json:"reverted_commits"` // Newest first, in the order they were reverted
+	Pushed          bool     `json:"pushed"`
+}
+
+// UndoBead closes a bead's pull requests, deletes its branches, and
+// reverts, newest first, its commits that remain on the checked-out
+// branch. Commits that only lived on the deleted branches go with them.
+// The working copy must be clean.
+func (s *GitService) UndoBead(ctx context.Context, req UndoBeadRequest) (*UndoBeadResult, error) {
+	startTime := time.Now()
+	if req.BeadID == "" {
+		return nil, fmt.Errorf("bead ID is required to undo a bead")
+	}
+	if req.Base == "" {
+		req.Base = "main"
+	}
+	if req.Reason == "" {
+		req.Reason = fmt.Sprintf("undo of bead %s", req.BeadID)
+	}
+
+	commits, err := s.GetBeadCommits(ctx, req.BeadID)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := s.beadBranches(ctx, req.BeadID, req.Base, req.Branches)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UndoBeadResult{}
+	current, err := s.getCurrentBranch(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current branch: %w", err)
+	}
+	for _, branch := range branches {
+		if branch == current {
+			if _, err := s.Checkout(ctx, CheckoutRequest{Branch: req.Base}); err != nil {
+				return nil, err
+			}
+			current = req.Base
+			break
+		}
+	}
+	result.Branch = current
+
+	// Close PRs before their branches go, so they are closed with a comment
+	for _, number := range req.PRNumbers {
+		if err := s.ClosePR(ctx, ClosePRRequest{
+			Number:  number,
+			BeadID:  req.BeadID,
+			Comment: fmt.Sprintf("Closed by %s", req.Reason),
+		}); err != nil {
+			return nil, err
+		}
+		result.ClosedPRs = append(result.ClosedPRs, number)
+	}
+
+	for _, branch := range branches {
+		if _, err := s.DeleteBranch(ctx, DeleteBranchRequest{Branch: branch, DeleteRemote: true}); err != nil {
+			return nil, err
+		}
+		result.DeletedBranches = append(result.DeletedBranches, branch)
+	}
+
+	// GetBeadCommits lists newest first, which is the order to revert in
+	var onBranch []string
+	for _, c := range commits {
+		reachable, err := s.IsAncestor(ctx, c.SHA, "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		if reachable {
+			onBranch = append(onBranch, c.SHA)
+		}
+	}
+	if len(onBranch) > 0 {
+		if _, err := s.Revert(ctx, RevertRequest{CommitSHAs: onBranch, BeadID: req.BeadID, Reason: req.Reason}); err != nil {
+			return nil, err
+		}
+		result.RevertedCommits = onBranch
+		if s.HasUpstream(ctx) {
+			if _, err := s.Push(ctx, PushRequest{BeadID: req.BeadID, Branch: current}); err != nil {
+				return nil, err
+			}
+			result.Pushed = true
+		}
+	}
+
+	s.auditLogger.LogOperationWithDuration("undo_bead", req.BeadID, current, true, nil, time.Since(startTime))
+	return result, nil
+}
+
+// beadBranches returns the given branches together with the bead's local
+// agent branches, leaving out the base, protected and missing ones
+func (s *GitService) beadBranches(ctx context.Context, beadID, base string, known []string) ([]string, error) {
+	output, err := s.git(ctx, "for-each-ref", "--format=%(refname:short)", "refs/heads/"+s.branchPrefix+beadID+"/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list branches of bead %s: %w", beadID, err)
+	}
+	candidates := append(append([]string{}, known...), strings.Fields(output)...)
+
+	var branches []string
+	seen := make(map[string]bool)
+	for _, branch := range candidates {
+		if branch == "" || branch == base || seen[branch] || isProtectedBranch(branch) {
+			continue
+		}
+		seen[branch] = true
+		exists, err := s.branchExists(ctx, branch)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			branches = append(branches, branch)
+		}
+	}
+	return branches, nil
+}
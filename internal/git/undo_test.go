@@ -0,0 +1,74 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUndoBead(t *testing.T) {
+	dir, cleanup := setupTestGitRepo(t)
+	defer cleanup()
+	svc := createTestGitService(t, dir)
+	ctx := context.Background()
+
+	base, err := svc.getCurrentBranch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The bead commits twice on the base branch, then once on its own branch
+	readme := filepath.Join(dir, "README.md")
+	for i, content := range []string{"# Test Repo\none\n", "# Test Repo\none\ntwo\n"} {
+		if err := os.WriteFile(readme, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := execGit(dir, "commit", "-am", "Step "+string(rune('1'+i))+"\n\nBead: bd-7"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := execGit(dir, "checkout", "-b", "agent/bd-7/fix"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fix.go"), []byte("package fix\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "add", "fix.go"); err != nil {
+		t.Fatal(err)
+	}
+	if err := execGit(dir, "commit", "-m", "Fix\n\nBead: bd-7"); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := svc.UndoBead(ctx, UndoBeadRequest{BeadID: "bd-7", Branches: []string{"main", "gone"}, Base: base})
+	if err != nil {
+		t.Fatalf("UndoBead failed: %v", err)
+	}
+
+	if result.Branch != base {
+		t.Errorf("reverts landed on %q, want %q", result.Branch, base)
+	}
+	if len(result.DeletedBranches) != 1 || result.DeletedBranches[0] != "agent/bd-7/fix" {
+		t.Errorf("deleted branches = %v, want only the bead's agent branch", result.DeletedBranches)
+	}
+	if len(result.RevertedCommits) != 2 {
+		t.Fatalf("reverted %v, want the two commits on %s", result.RevertedCommits, base)
+	}
+	if result.Pushed {
+		t.Error("a branch without upstream must not be pushed")
+	}
+	if exists, _ := svc.branchExists(ctx, "agent/bd-7/fix"); exists {
+		t.Error("bead branch still exists")
+	}
+	if data, _ := os.ReadFile(readme); string(data) != "# Test Repo\n" {
+		t.Errorf("README = %q, want the content before the bead", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "fix.go")); !os.IsNotExist(err) {
+		t.Error("file from the deleted branch is still present")
+	}
+
+	if _, err := svc.UndoBead(ctx, UndoBeadRequest{}); err == nil {
+		t.Error("expected an error without a bead ID")
+	}
+}
@@ -3,7 +3,6 @@ package gitops
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -12,6 +11,7 @@ import (
 
 	"github.com/jordanhubbard/loom/internal/database"
 	"github.com/jordanhubbard/loom/internal/keymanager"
+	"github.com/jordanhubbard/loom/internal/logging"
 	"github.com/jordanhubbard/loom/internal/observability"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
@@ -25,6 +25,10 @@ type Manager struct {
 	workDirOverrides map[string]string      // Per-project workdir overrides (e.g., loom-self → ".")
 }
 
+// gitLog is the structured logger of git operations; logGitEvent events
+// share its "git" module
+var gitLog = logging.Logger("git")
+
 func logGitEvent(event string, project *models.Project, fields map[string]interface{}) {
 	payload := make(map[string]interface{})
 	if project != nil {
@@ -898,14 +902,14 @@ func (m *Manager) restoreKeyFromDB(projectID string) bool {
 	// Decrypt private key from KeyManager
 	privateKeyData, err := m.keyManager.GetKey(cred.KeyID)
 	if err != nil {
-		log.Printf("[gitops] Failed to decrypt SSH key from DB for project %s: %v", projectID, err)
+		gitLog.Error("failed to decrypt stored SSH key", "project_id", projectID, "error", err)
 		return false
 	}
 
 	// Write to filesystem
 	keyDir := m.projectKeyDirForProject(projectID)
 	if err := os.MkdirAll(keyDir, 0700); err != nil {
-		log.Printf("[gitops] Failed to create SSH key directory for project %s: %v", projectID, err)
+		gitLog.Error("failed to create SSH key directory", "project_id", projectID, "error", err)
 		return false
 	}
 
@@ -913,11 +917,11 @@ func (m *Manager) restoreKeyFromDB(projectID string) bool {
 	publicPath := m.projectPublicKeyPath(projectID)
 
 	if err := os.WriteFile(privatePath, []byte(privateKeyData), 0600); err != nil {
-		log.Printf("[gitops] Failed to write private key for project %s: %v", projectID, err)
+		gitLog.Error("failed to write private key", "project_id", projectID, "error", err)
 		return false
 	}
 	if err := os.WriteFile(publicPath, []byte(cred.PublicKey), 0644); err != nil {
-		log.Printf("[gitops] Failed to write public key for project %s: %v", projectID, err)
+		gitLog.Error("failed to write public key", "project_id", projectID, "error", err)
 		return false
 	}
 
@@ -935,21 +939,21 @@ func (m *Manager) storeKeyInDBWithRotation(projectID, publicKey string, rotatedA
 		return
 	}
 	if !m.keyManager.IsUnlocked() {
-		log.Printf("[gitops] Cannot store SSH key in DB: key manager is locked")
+		gitLog.Warn("cannot store SSH key: key manager is locked", "project_id", projectID)
 		return
 	}
 
 	privatePath := m.projectPrivateKeyPath(projectID)
 	privateKeyBytes, err := os.ReadFile(privatePath)
 	if err != nil {
-		log.Printf("[gitops] Failed to read private key for DB storage (project %s): %v", projectID, err)
+		gitLog.Error("failed to read private key for storage", "project_id", projectID, "error", err)
 		return
 	}
 
 	// Store encrypted private key via KeyManager
 	keyID := fmt.Sprintf("ssh-%s", projectID)
 	if err := m.keyManager.StoreKey(keyID, fmt.Sprintf("SSH key for %s", projectID), "Auto-generated project deploy key", string(privateKeyBytes)); err != nil {
-		log.Printf("[gitops] Failed to encrypt SSH key for project %s: %v", projectID, err)
+		gitLog.Error("failed to encrypt SSH key", "project_id", projectID, "error", err)
 		return
 	}
 
@@ -969,7 +973,7 @@ func (m *Manager) storeKeyInDBWithRotation(projectID, publicKey string, rotatedA
 	}
 
 	if err := m.db.UpsertCredential(cred); err != nil {
-		log.Printf("[gitops] Failed to store credential in DB for project %s: %v", projectID, err)
+		gitLog.Error("failed to store SSH credential", "project_id", projectID, "error", err)
 		return
 	}
 
@@ -1000,12 +1004,12 @@ func (m *Manager) BackfillSSHCredentials(projects []*models.Project) {
 		// Read public key
 		publicKey, err := m.GetProjectPublicKey(p.ID)
 		if err != nil {
-			log.Printf("[gitops] Backfill: failed to read public key for project %s: %v", p.ID, err)
+			gitLog.Error("backfill: failed to read public key", "project_id", p.ID, "error", err)
 			continue
 		}
 
 		m.storeKeyInDB(p.ID, publicKey)
-		log.Printf("[gitops] Backfill: stored SSH key for project %s in database", p.ID)
+		gitLog.Info("backfill: stored SSH key", "project_id", p.ID)
 	}
 }
 
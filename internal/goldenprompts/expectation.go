@@ -0,0 +1,101 @@
+package goldenprompts
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Expectation types
+const (
+	ExpectContains     = "contains"       // The answer contains Value, ignoring case
+	ExpectNotContains  = "not_contains"   // The answer does not contain Value, ignoring case
+	ExpectRegex        = "regex"          // The answer matches the regular expression Value
+	ExpectJSON         = "json"           // The answer, without a code fence, is valid JSON
+	ExpectMinLength    = "min_length"     // The answer has at least Value characters
+	ExpectMaxLength    = "max_length"     // The answer has at most Value characters
+	ExpectMaxLatencyMs = "max_latency_ms" // The provider answered within Value milliseconds
+)
+
+// Expectation is one property a golden prompt's answer must have
+type Expectation struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+// Validate checks that the type is known and the value suits it
+func (e *Expectation) Validate() error {
+	switch e.Type {
+	case ExpectContains, ExpectNotContains:
+		if e.Value == "" {
+			return fmt.Errorf("%s needs a value", e.Type)
+		}
+	case ExpectRegex:
+		if _, err := regexp.Compile(e.Value); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case ExpectJSON:
+	case ExpectMinLength, ExpectMaxLength, ExpectMaxLatencyMs:
+		if n, err := strconv.Atoi(e.Value); err != nil || n < 0 {
+			return fmt.Errorf("%s needs a non-negative integer value", e.Type)
+		}
+	default:
+		return fmt.Errorf("unknown expectation type %q", e.Type)
+	}
+	return nil
+}
+
+// Check returns why response misses the expectation, or "" if it meets it
+func (e *Expectation) Check(response string, latencyMs int64) string {
+	switch e.Type {
+	case ExpectContains:
+		if !strings.Contains(strings.ToLower(response), strings.ToLower(e.Value)) {
+			return fmt.Sprintf("does not contain %q", e.Value)
+		}
+	case ExpectNotContains:
+		if strings.Contains(strings.ToLower(response), strings.ToLower(e.Value)) {
+			return fmt.Sprintf("contains %q", e.Value)
+		}
+	case ExpectRegex:
+		re, err := regexp.Compile(e.Value)
+		if err != nil || !re.MatchString(response) {
+			return fmt.Sprintf("does not match /%s/", e.Value)
+		}
+	case ExpectJSON:
+		if !json.Valid([]byte(stripFence(response))) {
+			return "is not valid JSON"
+		}
+	case ExpectMinLength:
+		n, _ := strconv.Atoi(e.Value)
+		if len([]rune(response)) < n {
+			return fmt.Sprintf("is shorter than %d characters", n)
+		}
+	case ExpectMaxLength:
+		n, _ := strconv.Atoi(e.Value)
+		if len([]rune(response)) > n {
+			return fmt.Sprintf("is longer than %d characters", n)
+		}
+	case ExpectMaxLatencyMs:
+		n, _ := strconv.Atoi(e.Value)
+		if latencyMs > int64(n) {
+			return fmt.Sprintf("took %dms, more than %dms", latencyMs, n)
+		}
+	}
+	return ""
+}
+
+// stripFence removes a Markdown code fence around an answer, which models
+// often add to JSON
+func stripFence(s string) string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "```") {
+		return s
+	}
+	s = strings.TrimPrefix(s, "```")
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(s), "```"))
+}
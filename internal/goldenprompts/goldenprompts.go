@@ -0,0 +1,530 @@
+// Package goldenprompts keeps curated prompt regression tests per project.
+// A case is a prompt and the behavior expected of the answer; a run sends
+// every enabled case of a project to the configured providers, scores the
+// answers against the expectations and compares them with the previous
+// run. A case whose score drops on a provider is a regression, and a run
+// with regressions is reported to whoever is listening.
+package goldenprompts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Run triggers
+const (
+	TriggerManual      = "manual"
+	TriggerModelChange = "model_change"   // A provider's model changed
+	TriggerRouting     = "routing_change" // The routing configuration changed
+)
+
+// Run statuses
+const (
+	RunPassed    = "passed"    // Every case met every expectation
+	RunFailed    = "failed"    // Some cases fail, but none worse than last run
+	RunRegressed = "regressed" // Some case scored lower than in the previous run
+)
+
+// maxResponseChars bounds the answer kept with each result
+const maxResponseChars = 4000
+
+// baselineRuns is how many earlier runs are searched for a case's baseline
+const baselineRuns = 20
+
+// Case is one golden prompt and what its answer must look like
+type Case struct {
+	ID           string        `json:"id"`
+	ProjectID    string        `json:"project_id"`
+	Name         string        `json:"name"`
+	SystemPrompt string        `json:"system_prompt,omitempty"`
+	Prompt       string        `json:"prompt"`
+	Expectations []Expectation `json:"expectations"`
+	ProviderIDs  []string      `json:"provider_ids,omitempty"` // Empty runs the case on every active provider
+	Enabled      bool          `json:"enabled"`
+	CreatedBy    string        `json:"created_by,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	UpdatedAt    time.Time     `json:"updated_at"`
+}
+
+// CaseRequest creates or updates a case. On update, nil fields are left
+// unchanged.
+type CaseRequest struct {
+	Name         *string        `json:"name,omitempty"`
+	SystemPrompt *string        `json:"system_prompt,omitempty"`
+	Prompt       *string        `json:"prompt,omitempty"`
+	Expectations *[]Expectation `json:"expectations,omitempty"`
+	ProviderIDs  *[]string      `json:"provider_ids,omitempty"`
+	Enabled      *bool          `json:"enabled,omitempty"` // Defaults to true on create
+}
+
+// Result is one case answered by one provider
+type Result struct {
+	CaseID     string   `json:"case_id"`
+	CaseName   string   `json:"case_name"`
+	ProviderID string   `json:"provider_id"`
+	Model      string   `json:"model,omitempty"`
+	Passed     bool     `json:"passed"`
+	Score      float64  `json:"score"` // Fraction of expectations met, 0 on error
+	Failures   []string `json:"failures,omitempty"`
+	Response   string   `json:"response,omitempty"`
+	LatencyMs  int64    `json:"latency_ms"`
+	Error      string   `json:"error,omitempty"`
+
+	BaselineScore *float64 `json:"baseline_score,omitempty"` // Score in the last run that had this case and provider
+	Regressed     bool     `json:"regressed"`
+}
+
+// Run is one execution of a project's cases
+type Run struct {
+	ID          string    `json:"id"`
+	ProjectID   string    `json:"project_id"`
+	Trigger     string    `json:"trigger"`
+	Reason      string    `json:"reason,omitempty"` // What prompted an automatic run
+	Status      string    `json:"status"`
+	Cases       int       `json:"cases"`
+	Passed      int       `json:"passed"` // Results, not cases: a case counts once per provider
+	Failed      int       `json:"failed"`
+	Regressions int       `json:"regressions"`
+	Results     []Result  `json:"results"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+}
+
+// Store persists cases and runs
+type Store interface {
+	// SaveGoldenCase inserts or replaces a case
+	SaveGoldenCase(c *Case) error
+	// GetGoldenCase returns a case, or nil if unknown
+	GetGoldenCase(id string) (*Case, error)
+	// ListGoldenCases returns a project's cases, or every case when
+	// projectID is empty
+	ListGoldenCases(projectID string) ([]*Case, error)
+	// DeleteGoldenCase removes a case
+	DeleteGoldenCase(id string) error
+	// SaveGoldenRun inserts or replaces a run
+	SaveGoldenRun(r *Run) error
+	// GetGoldenRun returns a run, or nil if unknown
+	GetGoldenRun(id string) (*Run, error)
+	// ListGoldenRuns returns a project's runs, newest first; limit 0 means
+	// no limit
+	ListGoldenRuns(projectID string, limit int) ([]*Run, error)
+}
+
+// Target is a provider cases can run on
+type Target struct {
+	ProviderID string
+	Model      string
+}
+
+// Completer sends golden prompts to providers
+type Completer interface {
+	// Targets returns the active providers
+	Targets() []Target
+	// Complete answers prompt on a provider and names the model that did
+	Complete(ctx context.Context, providerID, systemPrompt, prompt string) (response, model string, err error)
+}
+
+// Config tunes the runner
+type Config struct {
+	Timeout     time.Duration // Per completion
+	ChangeDelay time.Duration // Quiet time after a model or routing change before the suites run
+}
+
+// DefaultConfig gives each completion two minutes and waits a minute after
+// the last change before rerunning the suites
+func DefaultConfig() Config {
+	return Config{Timeout: 2 * time.Minute, ChangeDelay: time.Minute}
+}
+
+// Manager owns the cases and runs them. Changed and Close do nothing on a
+// nil Manager.
+type Manager struct {
+	store     Store
+	completer Completer
+	cfg       Config
+
+	mu           sync.Mutex
+	running      map[string]bool // Projects with a run in progress
+	onRegression func(*Run)
+	changeTimer  *time.Timer
+	changeReason string
+	changeKind   string
+	closed       bool
+}
+
+// NewManager creates a manager backed by store that runs cases through
+// completer
+func NewManager(store Store, completer Completer, cfg Config) *Manager {
+	if store == nil || completer == nil {
+		return nil
+	}
+	def := DefaultConfig()
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	if cfg.ChangeDelay <= 0 {
+		cfg.ChangeDelay = def.ChangeDelay
+	}
+	return &Manager{store: store, completer: completer, cfg: cfg, running: make(map[string]bool)}
+}
+
+// OnRegression registers the function told about every run with
+// regressions
+func (m *Manager) OnRegression(fn func(*Run)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onRegression = fn
+}
+
+// validate checks the fields a case needs to run
+func (c *Case) validate() error {
+	if c.ProjectID == "" {
+		return fmt.Errorf("project_id must not be empty")
+	}
+	if strings.TrimSpace(c.Name) == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	if strings.TrimSpace(c.Prompt) == "" {
+		return fmt.Errorf("prompt must not be empty")
+	}
+	if len(c.Expectations) == 0 {
+		return fmt.Errorf("expectations must list at least one expectation")
+	}
+	for i := range c.Expectations {
+		if err := c.Expectations[i].Validate(); err != nil {
+			return fmt.Errorf("expectation %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// apply copies the set fields of req onto c
+func (req *CaseRequest) apply(c *Case) {
+	if req.Name != nil {
+		c.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.SystemPrompt != nil {
+		c.SystemPrompt = *req.SystemPrompt
+	}
+	if req.Prompt != nil {
+		c.Prompt = *req.Prompt
+	}
+	if req.Expectations != nil {
+		c.Expectations = *req.Expectations
+	}
+	if req.ProviderIDs != nil {
+		c.ProviderIDs = *req.ProviderIDs
+	}
+	if req.Enabled != nil {
+		c.Enabled = *req.Enabled
+	}
+}
+
+// Create adds a case to a project
+func (m *Manager) Create(projectID string, req CaseRequest, createdBy string) (*Case, error) {
+	now := time.Now().UTC()
+	c := &Case{
+		ID:        uuid.New().String(),
+		ProjectID: projectID,
+		Enabled:   true,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req.apply(c)
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid golden prompt: %w", err)
+	}
+	if err := m.store.SaveGoldenCase(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns one of a project's cases
+func (m *Manager) Get(projectID, id string) (*Case, error) {
+	c, err := m.store.GetGoldenCase(id)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil || c.ProjectID != projectID {
+		return nil, fmt.Errorf("golden prompt not found: %s", id)
+	}
+	return c, nil
+}
+
+// List returns a project's cases
+func (m *Manager) List(projectID string) ([]*Case, error) {
+	return m.store.ListGoldenCases(projectID)
+}
+
+// Update changes the fields set in req
+func (m *Manager) Update(projectID, id string, req CaseRequest) (*Case, error) {
+	c, err := m.Get(projectID, id)
+	if err != nil {
+		return nil, err
+	}
+	req.apply(c)
+	if err := c.validate(); err != nil {
+		return nil, fmt.Errorf("invalid golden prompt: %w", err)
+	}
+	c.UpdatedAt = time.Now().UTC()
+	if err := m.store.SaveGoldenCase(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Delete removes a case; its results stay in earlier runs
+func (m *Manager) Delete(projectID, id string) error {
+	if _, err := m.Get(projectID, id); err != nil {
+		return err
+	}
+	return m.store.DeleteGoldenCase(id)
+}
+
+// GetRun returns one of a project's runs
+func (m *Manager) GetRun(projectID, id string) (*Run, error) {
+	r, err := m.store.GetGoldenRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || r.ProjectID != projectID {
+		return nil, fmt.Errorf("golden prompt run not found: %s", id)
+	}
+	return r, nil
+}
+
+// Runs returns a project's most recent runs, newest first
+func (m *Manager) Runs(projectID string, limit int) ([]*Run, error) {
+	return m.store.ListGoldenRuns(projectID, limit)
+}
+
+// Run runs a project's enabled cases now and records the run
+func (m *Manager) Run(ctx context.Context, projectID, trigger, reason string) (*Run, error) {
+	m.mu.Lock()
+	if m.running[projectID] {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("golden prompts of %s are already running", projectID)
+	}
+	m.running[projectID] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, projectID)
+		m.mu.Unlock()
+	}()
+
+	cases, err := m.store.ListGoldenCases(projectID)
+	if err != nil {
+		return nil, err
+	}
+	run := &Run{
+		ID:        uuid.New().String(),
+		ProjectID: projectID,
+		Trigger:   trigger,
+		Reason:    reason,
+		StartedAt: time.Now().UTC(),
+	}
+
+	targets := m.completer.Targets()
+	models := make(map[string]string, len(targets))
+	for _, t := range targets {
+		models[t.ProviderID] = t.Model
+	}
+	for _, c := range cases {
+		if !c.Enabled {
+			continue
+		}
+		run.Cases++
+		providerIDs := c.ProviderIDs
+		if len(providerIDs) == 0 {
+			for _, t := range targets {
+				providerIDs = append(providerIDs, t.ProviderID)
+			}
+		}
+		for _, providerID := range providerIDs {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			model, active := models[providerID]
+			run.Results = append(run.Results, m.runCase(ctx, c, providerID, model, active))
+		}
+	}
+
+	m.compare(run)
+	run.FinishedAt = time.Now().UTC()
+	if err := m.store.SaveGoldenRun(run); err != nil {
+		return nil, err
+	}
+
+	if run.Status == RunRegressed {
+		log.Printf("[GoldenPrompts] %d regression(s) in project %s (run %s, %s)", run.Regressions, projectID, run.ID, trigger)
+		m.mu.Lock()
+		notify := m.onRegression
+		m.mu.Unlock()
+		if notify != nil {
+			notify(run)
+		}
+	}
+	return run, nil
+}
+
+// runCase answers one case on one provider and scores the answer
+func (m *Manager) runCase(ctx context.Context, c *Case, providerID, model string, active bool) Result {
+	res := Result{CaseID: c.ID, CaseName: c.Name, ProviderID: providerID, Model: model}
+	if !active {
+		res.Error = "provider not active"
+		return res
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	response, usedModel, err := m.completer.Complete(cctx, providerID, c.SystemPrompt, c.Prompt)
+	res.LatencyMs = time.Since(start).Milliseconds()
+	if usedModel != "" {
+		res.Model = usedModel
+	}
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	if len(response) > maxResponseChars {
+		res.Response = response[:maxResponseChars]
+	} else {
+		res.Response = response
+	}
+
+	met := 0
+	for _, e := range c.Expectations {
+		if failure := e.Check(response, res.LatencyMs); failure != "" {
+			res.Failures = append(res.Failures, failure)
+			continue
+		}
+		met++
+	}
+	res.Score = float64(met) / float64(len(c.Expectations))
+	res.Passed = met == len(c.Expectations)
+	return res
+}
+
+// compare marks the results that scored lower than the last earlier run
+// with the same case and provider, and sets the run's tallies and status
+func (m *Manager) compare(run *Run) {
+	previous, err := m.store.ListGoldenRuns(run.ProjectID, baselineRuns)
+	if err != nil {
+		log.Printf("[GoldenPrompts] No baseline for project %s: %v", run.ProjectID, err)
+	}
+	for i := range run.Results {
+		res := &run.Results[i]
+		if base, ok := baseline(previous, res.CaseID, res.ProviderID); ok {
+			res.BaselineScore = &base
+			res.Regressed = res.Score < base
+		}
+		if res.Passed {
+			run.Passed++
+		} else {
+			run.Failed++
+		}
+		if res.Regressed {
+			run.Regressions++
+		}
+	}
+	switch {
+	case run.Regressions > 0:
+		run.Status = RunRegressed
+	case run.Failed > 0:
+		run.Status = RunFailed
+	default:
+		run.Status = RunPassed
+	}
+}
+
+// baseline returns the score of a case on a provider in the most recent of
+// runs that has it
+func baseline(runs []*Run, caseID, providerID string) (float64, bool) {
+	for _, r := range runs {
+		for _, res := range r.Results {
+			if res.CaseID == caseID && res.ProviderID == providerID {
+				return res.Score, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// RunAll runs the cases of every project that has any, one project after
+// the other, and returns the runs that completed
+func (m *Manager) RunAll(ctx context.Context, trigger, reason string) []*Run {
+	cases, err := m.store.ListGoldenCases("")
+	if err != nil {
+		log.Printf("[GoldenPrompts] Failed to list cases: %v", err)
+		return nil
+	}
+	seen := make(map[string]bool)
+	var runs []*Run
+	for _, c := range cases {
+		if !c.Enabled || seen[c.ProjectID] {
+			continue
+		}
+		seen[c.ProjectID] = true
+		run, err := m.Run(ctx, c.ProjectID, trigger, reason)
+		if err != nil {
+			log.Printf("[GoldenPrompts] Run for project %s failed: %v", c.ProjectID, err)
+			continue
+		}
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+// Changed schedules the suites of every project to run once models or
+// routing have stopped changing for the configured delay. Changes in the
+// meantime push the run back and are summarized in its reason.
+func (m *Manager) Changed(trigger, reason string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	switch {
+	case m.changeReason == "":
+		m.changeKind, m.changeReason = trigger, reason
+	case !strings.Contains(m.changeReason, reason):
+		m.changeReason += "; " + reason
+	}
+	if m.changeTimer != nil {
+		m.changeTimer.Stop()
+	}
+	m.changeTimer = time.AfterFunc(m.cfg.ChangeDelay, func() {
+		m.mu.Lock()
+		trigger, reason := m.changeKind, m.changeReason
+		m.changeKind, m.changeReason, m.changeTimer = "", "", nil
+		m.mu.Unlock()
+		m.RunAll(context.Background(), trigger, reason)
+	})
+}
+
+// Close cancels a pending change-triggered run
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	if m.changeTimer != nil {
+		m.changeTimer.Stop()
+		m.changeTimer = nil
+	}
+}
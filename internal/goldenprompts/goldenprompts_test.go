@@ -0,0 +1,36 @@
+package goldenprompts
+
+import "testing"
+
+func TestExpectations(t *testing.T) {
+	tests := []struct {
+		exp      Expectation
+		response string
+		latency  int64
+		pass     bool
+	}{
+		{Expectation{ExpectContains, "Answer"}, "the answer is 42", 0, true},
+		{Expectation{ExpectContains, "question"}, "the answer is 42", 0, false},
+		{Expectation{ExpectNotContains, "sorry"}, "Sorry, I can't", 0, false},
+		{Expectation{ExpectRegex, `\b42\b`}, "the answer is 42", 0, true},
+		{Expectation{ExpectJSON, ""}, "```json\n{\"a\": 1}\n```", 0, true},
+		{Expectation{ExpectJSON, ""}, "a: 1", 0, false},
+		{Expectation{ExpectMinLength, "5"}, "ok", 0, false},
+		{Expectation{ExpectMaxLength, "5"}, "ok", 0, true},
+		{Expectation{ExpectMaxLatencyMs, "100"}, "ok", 250, false},
+	}
+	for _, tt := range tests {
+		if err := tt.exp.Validate(); err != nil {
+			t.Fatalf("%+v: %v", tt.exp, err)
+		}
+		if got := tt.exp.Check(tt.response, tt.latency) == ""; got != tt.pass {
+			t.Errorf("%+v on %q: pass = %v, want %v", tt.exp, tt.response, got, tt.pass)
+		}
+	}
+
+	for _, bad := range []Expectation{{"sounds_right", ""}, {ExpectRegex, "("}, {ExpectMinLength, "many"}, {ExpectContains, ""}} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: expected validation error", bad)
+		}
+	}
+}
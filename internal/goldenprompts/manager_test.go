@@ -0,0 +1,200 @@
+package goldenprompts_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/reports"
+)
+
+// fakeCompleter answers from a table keyed by provider
+type fakeCompleter struct {
+	mu      sync.Mutex
+	answers map[string]string
+	calls   int
+}
+
+func (f *fakeCompleter) Targets() []goldenprompts.Target {
+	return []goldenprompts.Target{{ProviderID: "p1", Model: "m1"}, {ProviderID: "p2", Model: "m2"}}
+}
+
+func (f *fakeCompleter) Complete(ctx context.Context, providerID, systemPrompt, prompt string) (string, string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	answer, ok := f.answers[providerID]
+	if !ok {
+		return "", "", fmt.Errorf("provider %s is down", providerID)
+	}
+	return answer, "", nil
+}
+
+func (f *fakeCompleter) set(providerID, answer string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.answers[providerID] = answer
+}
+
+func strp(s string) *string { return &s }
+
+func newTestManager(t *testing.T) (*goldenprompts.Manager, *fakeCompleter) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "goldenprompts.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	fc := &fakeCompleter{answers: map[string]string{
+		"p1": `{"status": "ok", "answer": 42}`,
+		"p2": `{"status": "ok", "answer": 42}`,
+	}}
+	return goldenprompts.NewManager(db, fc, goldenprompts.Config{ChangeDelay: 10 * time.Millisecond}), fc
+}
+
+func TestCaseCRUD(t *testing.T) {
+	mgr, _ := newTestManager(t)
+	exps := []goldenprompts.Expectation{{Type: goldenprompts.ExpectJSON}}
+
+	if _, err := mgr.Create("proj-1", goldenprompts.CaseRequest{Name: strp("no prompt"), Expectations: &exps}, ""); err == nil {
+		t.Error("expected error for missing prompt")
+	}
+	c, err := mgr.Create("proj-1", goldenprompts.CaseRequest{Name: strp("json"), Prompt: strp("Reply in JSON"), Expectations: &exps}, "u-1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if !c.Enabled || c.CreatedBy != "u-1" {
+		t.Errorf("unexpected case %+v", c)
+	}
+
+	if _, err := mgr.Get("proj-2", c.ID); err == nil {
+		t.Error("case should not be visible from another project")
+	}
+	disabled := false
+	updated, err := mgr.Update("proj-1", c.ID, goldenprompts.CaseRequest{Enabled: &disabled})
+	if err != nil || updated.Enabled || updated.Prompt != "Reply in JSON" {
+		t.Fatalf("Update: %+v, %v", updated, err)
+	}
+	if err := mgr.Delete("proj-1", c.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if list, _ := mgr.List("proj-1"); len(list) != 0 {
+		t.Errorf("expected no cases, got %d", len(list))
+	}
+}
+
+func TestRunDetectsRegression(t *testing.T) {
+	mgr, fc := newTestManager(t)
+	exps := []goldenprompts.Expectation{{Type: goldenprompts.ExpectJSON}, {Type: goldenprompts.ExpectContains, Value: `"status": "ok"`}}
+	if _, err := mgr.Create("proj-1", goldenprompts.CaseRequest{Name: strp("status"), Prompt: strp("Status as JSON"), Expectations: &exps}, ""); err != nil {
+		t.Fatal(err)
+	}
+	var notified []*goldenprompts.Run
+	mgr.OnRegression(func(r *goldenprompts.Run) { notified = append(notified, r) })
+	ctx := context.Background()
+
+	first, err := mgr.Run(ctx, "proj-1", goldenprompts.TriggerManual, "")
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if first.Status != goldenprompts.RunPassed || first.Passed != 2 || first.Results[0].BaselineScore != nil {
+		t.Fatalf("unexpected first run %+v", first)
+	}
+
+	fc.set("p2", "Status: ok")
+	second, err := mgr.Run(ctx, "proj-1", goldenprompts.TriggerModelChange, "p2 now uses m3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Status != goldenprompts.RunRegressed || second.Regressions != 1 || second.Passed != 1 {
+		t.Fatalf("unexpected second run %+v", second)
+	}
+	var regressed *goldenprompts.Result
+	for i := range second.Results {
+		if second.Results[i].Regressed {
+			regressed = &second.Results[i]
+		}
+	}
+	if regressed == nil || regressed.ProviderID != "p2" || *regressed.BaselineScore != 1 || len(regressed.Failures) != 2 {
+		t.Fatalf("unexpected regressed result %+v", regressed)
+	}
+	if len(notified) != 1 || notified[0].ID != second.ID {
+		t.Errorf("expected one regression notification, got %d", len(notified))
+	}
+
+	// Still failing, but no worse than last time
+	third, _ := mgr.Run(ctx, "proj-1", goldenprompts.TriggerManual, "")
+	if third.Status != goldenprompts.RunFailed || third.Regressions != 0 {
+		t.Errorf("unexpected third run %+v", third)
+	}
+
+	runs, _ := mgr.Runs("proj-1", 2)
+	if len(runs) != 2 || runs[0].ID != third.ID {
+		t.Errorf("runs not newest first: %+v", runs)
+	}
+
+	report := goldenprompts.RunReport(second)
+	if len(report.Tables) == 0 || report.Tables[0].Title != "Regressions" {
+		t.Fatalf("regressions should come first: %+v", report.Tables)
+	}
+	if _, err := reports.Render(report, reports.FormatHTML, nil); err != nil {
+		t.Errorf("Render: %v", err)
+	}
+	summary := goldenprompts.PeriodReport(runs, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	if summary.Summary[0].Value != "2" || len(summary.Tables) != 2 || len(summary.Tables[1].Rows) != 1 {
+		t.Errorf("unexpected period report %+v", summary)
+	}
+}
+
+func TestRunUsesCaseProviders(t *testing.T) {
+	mgr, fc := newTestManager(t)
+	exps := []goldenprompts.Expectation{{Type: goldenprompts.ExpectContains, Value: "ok"}}
+	providers := []string{"p1", "gone"}
+	if _, err := mgr.Create("proj-1", goldenprompts.CaseRequest{Name: strp("pinned"), Prompt: strp("Say ok"), Expectations: &exps, ProviderIDs: &providers}, ""); err != nil {
+		t.Fatal(err)
+	}
+	run, err := mgr.Run(context.Background(), "proj-1", goldenprompts.TriggerManual, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(run.Results) != 2 || fc.calls != 1 {
+		t.Fatalf("expected two results from one completion, got %d results, %d calls", len(run.Results), fc.calls)
+	}
+	if r := run.Results[1]; r.Passed || !strings.Contains(r.Error, "not active") {
+		t.Errorf("inactive provider should fail: %+v", r)
+	}
+}
+
+func TestChangedDebounces(t *testing.T) {
+	mgr, fc := newTestManager(t)
+	exps := []goldenprompts.Expectation{{Type: goldenprompts.ExpectJSON}}
+	if _, err := mgr.Create("proj-1", goldenprompts.CaseRequest{Name: strp("json"), Prompt: strp("JSON"), Expectations: &exps}, ""); err != nil {
+		t.Fatal(err)
+	}
+	mgr.Changed(goldenprompts.TriggerModelChange, "p1 now uses m9")
+	mgr.Changed(goldenprompts.TriggerRouting, "routing policy changed")
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if runs, _ := mgr.Runs("proj-1", 0); len(runs) > 0 {
+			if len(runs) != 1 || runs[0].Trigger != goldenprompts.TriggerModelChange || !strings.Contains(runs[0].Reason, "routing") {
+				t.Fatalf("unexpected runs %+v", runs[0])
+			}
+			fc.mu.Lock()
+			calls := fc.calls
+			fc.mu.Unlock()
+			if calls != 2 {
+				t.Errorf("expected one run over two providers, got %d calls", calls)
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("change did not trigger a run")
+}
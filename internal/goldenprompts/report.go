@@ -0,0 +1,156 @@
+package goldenprompts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/reports"
+)
+
+// maxReportResponse bounds the answer excerpt shown in report tables
+const maxReportResponse = 200
+
+// RunReport describes a run as a report, regressions first
+func RunReport(run *Run) *reports.Report {
+	r := &reports.Report{
+		Title:       "Golden Prompt Run " + run.ProjectID,
+		PeriodStart: run.StartedAt,
+		PeriodEnd:   run.FinishedAt,
+		GeneratedAt: time.Now().UTC(),
+		Summary: []reports.Metric{
+			{Label: "Status", Value: run.Status},
+			{Label: "Trigger", Value: run.Trigger},
+			{Label: "Cases", Value: fmt.Sprintf("%d", run.Cases)},
+			{Label: "Passed", Value: fmt.Sprintf("%d", run.Passed)},
+			{Label: "Failed", Value: fmt.Sprintf("%d", run.Failed)},
+			{Label: "Regressions", Value: fmt.Sprintf("%d", run.Regressions)},
+		},
+	}
+	if run.Reason != "" {
+		r.Summary = append(r.Summary, reports.Metric{Label: "Reason", Value: run.Reason})
+	}
+
+	columns := []string{"Case", "Provider", "Model", "Score", "Baseline", "Latency (ms)", "Failures", "Response"}
+	regressions := reports.Table{Title: "Regressions", Columns: columns}
+	failures := reports.Table{Title: "Failures", Columns: columns}
+	passes := reports.Table{Title: "Passed", Columns: columns}
+	for _, res := range run.Results {
+		row := resultRow(res)
+		switch {
+		case res.Regressed:
+			regressions.Rows = append(regressions.Rows, row)
+		case !res.Passed:
+			failures.Rows = append(failures.Rows, row)
+		default:
+			passes.Rows = append(passes.Rows, row)
+		}
+	}
+	for _, t := range []reports.Table{regressions, failures, passes} {
+		if len(t.Rows) > 0 {
+			r.Tables = append(r.Tables, t)
+		}
+	}
+	return r
+}
+
+// resultRow formats a result for a report table
+func resultRow(res Result) []string {
+	baseline := "-"
+	if res.BaselineScore != nil {
+		baseline = fmt.Sprintf("%.2f", *res.BaselineScore)
+	}
+	failures := res.Error
+	for _, f := range res.Failures {
+		if failures != "" {
+			failures += "; "
+		}
+		failures += f
+	}
+	response := []rune(res.Response)
+	if len(response) > maxReportResponse {
+		response = append(response[:maxReportResponse], '…')
+	}
+	return []string{
+		res.CaseName,
+		res.ProviderID,
+		res.Model,
+		fmt.Sprintf("%.2f", res.Score),
+		baseline,
+		fmt.Sprintf("%d", res.LatencyMs),
+		failures,
+		string(response),
+	}
+}
+
+// PeriodReport summarizes the runs of several projects between start and
+// end; runs outside the period are ignored
+func PeriodReport(runs []*Run, start, end time.Time) *reports.Report {
+	projects := reports.Table{Title: "Projects", Columns: []string{"Project", "Runs", "Regressed runs", "Last status", "Last run"}}
+	regressions := reports.Table{Title: "Regressions", Columns: []string{"Project", "Run", "Case", "Provider", "Model", "Score", "Baseline"}}
+
+	type tally struct {
+		runs, regressed int
+		last            *Run
+	}
+	byProject := make(map[string]*tally)
+	var order []string
+	var total, regressed, results, passed int
+	for _, run := range runs {
+		if run.StartedAt.Before(start) || !run.StartedAt.Before(end) {
+			continue
+		}
+		t := byProject[run.ProjectID]
+		if t == nil {
+			t = &tally{}
+			byProject[run.ProjectID] = t
+			order = append(order, run.ProjectID)
+		}
+		t.runs++
+		total++
+		results += len(run.Results)
+		passed += run.Passed
+		if t.last == nil || run.StartedAt.After(t.last.StartedAt) {
+			t.last = run
+		}
+		if run.Status != RunRegressed {
+			continue
+		}
+		t.regressed++
+		regressed++
+		for _, res := range run.Results {
+			if !res.Regressed {
+				continue
+			}
+			row := resultRow(res)
+			regressions.Rows = append(regressions.Rows, []string{run.ProjectID, run.ID, row[0], row[1], row[2], row[3], row[4]})
+		}
+	}
+	for _, id := range order {
+		t := byProject[id]
+		projects.Rows = append(projects.Rows, []string{
+			id,
+			fmt.Sprintf("%d", t.runs),
+			fmt.Sprintf("%d", t.regressed),
+			t.last.Status,
+			t.last.StartedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	passRate := "-"
+	if results > 0 {
+		passRate = fmt.Sprintf("%.1f%%", 100*float64(passed)/float64(results))
+	}
+	r := &reports.Report{
+		Title: "Golden Prompt Report",
+		Summary: []reports.Metric{
+			{Label: "Runs", Value: fmt.Sprintf("%d", total)},
+			{Label: "Regressed runs", Value: fmt.Sprintf("%d", regressed)},
+			{Label: "Pass rate", Value: passRate},
+		},
+		Tables: []reports.Table{projects},
+	}
+	if len(regressions.Rows) > 0 {
+		r.Tables = append(r.Tables, regressions)
+	}
+	return r
+}
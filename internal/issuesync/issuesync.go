@@ -0,0 +1,588 @@
+// Package issuesync keeps beads in step with an external issue tracker,
+// GitHub Issues or Jira. Issues are imported as beads and the beads are
+// updated whenever the tracker changes them, by polling or by webhook. In
+// the other direction, a bead's status changes and comments are pushed back
+// to its issue. Every imported bead records the issue it came from, which
+// lets a team keep the tracker as the source of truth for what to work on.
+package issuesync
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Trackers
+const (
+	TrackerGitHub = "github"
+	TrackerJira   = "jira"
+)
+
+// Issue states, as the sync sees them across trackers
+const (
+	StateOpen       = "open"
+	StateInProgress = "in_progress"
+	StateClosed     = "closed"
+)
+
+// Bead context keys recording the issue a bead was imported from
+const (
+	ContextTracker    = "external_tracker"
+	ContextExternalID = "external_id"
+	ContextURL        = "external_url"
+)
+
+// Outcomes of applying an issue
+const (
+	OutcomeImported  = "imported"
+	OutcomeUpdated   = "updated"
+	OutcomeUnchanged = "unchanged"
+	OutcomeSkipped   = "skipped" // Closed issues and issues without the project's labels are not imported
+)
+
+// CommentPrefix starts every comment pushed to a tracker. Comments coming
+// back with it are not imported again.
+const CommentPrefix = "[loom] "
+
+// Issue is an issue as read from a tracker
+type Issue struct {
+	Key       string    `json:"key"` // Number on GitHub, issue key such as OPS-7 on Jira
+	Title     string    `json:"title"`
+	Body      string    `json:"body,omitempty"`
+	State     string    `json:"state"`              // open, in_progress or closed
+	Priority  string    `json:"priority,omitempty"` // The tracker's priority name, if it has one
+	Labels    []string  `json:"labels,omitempty"`
+	URL       string    `json:"url,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Link ties a bead to the issue it was imported from
+type Link struct {
+	ProjectID      string    `json:"project_id"`
+	Tracker        string    `json:"tracker"`
+	ExternalID     string    `json:"external_id"`
+	BeadID         string    `json:"bead_id"`
+	URL            string    `json:"url,omitempty"`
+	IssueState     string    `json:"issue_state"` // Last seen on, or pushed to, the issue
+	IssueUpdatedAt time.Time `json:"issue_updated_at"`
+	SyncedAt       time.Time `json:"synced_at"`
+}
+
+// Result counts what a sync of a project did
+type Result struct {
+	ProjectID string   `json:"project_id"`
+	Imported  int      `json:"imported"`
+	Updated   int      `json:"updated"`
+	Unchanged int      `json:"unchanged"`
+	Errors    []string `json:"errors,omitempty"`
+}
+
+// Store persists links
+type Store interface {
+	// SaveIssueLink inserts or replaces a link
+	SaveIssueLink(l *Link) error
+	// GetIssueLink returns the link of an issue, or nil if it was not
+	// imported
+	GetIssueLink(projectID, tracker, externalID string) (*Link, error)
+	// GetIssueLinkByBead returns the link of a bead, or nil if it was not
+	// imported
+	GetIssueLinkByBead(beadID string) (*Link, error)
+	// ListIssueLinks returns a project's links, most recently synced first
+	ListIssueLinks(projectID string) ([]*Link, error)
+}
+
+// Tracker reads and writes the issues of one project's tracker
+type Tracker interface {
+	// Issues returns the issues updated since, or every issue when since
+	// is zero
+	Issues(ctx context.Context, since time.Time) ([]Issue, error)
+	// SetState moves an issue to open, in_progress or closed
+	SetState(ctx context.Context, key, state string) error
+	// Comment adds a comment to an issue
+	Comment(ctx context.Context, key, body string) error
+}
+
+// Fields are the parts of a bead an issue sets
+type Fields struct {
+	Title       string
+	Description string
+	Status      models.BeadStatus // Empty leaves the status alone
+	Priority    models.BeadPriority
+	Type        string
+	Tags        []string
+	Context     map[string]string
+}
+
+// Beads files and updates the beads of imported issues
+type Beads interface {
+	// CreateBead files a bead in a project and returns its ID
+	CreateBead(projectID string, f Fields) (string, error)
+	// UpdateBead applies an issue's fields to its bead
+	UpdateBead(beadID string, f Fields) error
+	// AddComment adds a tracker comment to a bead
+	AddComment(beadID, author, body string) error
+}
+
+// Project is a project whose beads sync with a tracker
+type Project struct {
+	ProjectID string   `json:"project_id"`
+	Tracker   string   `json:"tracker"` // github or jira
+	Ref       string   `json:"ref"`     // GitHub owner/name or Jira project key
+	Labels    []string `json:"labels,omitempty"`
+	Client    Tracker  `json:"-"`
+}
+
+// Config tunes the sync
+type Config struct {
+	Enabled     bool
+	Interval    time.Duration  // 0 relies on webhooks
+	PriorityMap map[string]int // Priority names and labels to bead priorities, over DefaultPriorityMap
+}
+
+// DefaultPriorityMap maps common tracker priorities and priority labels
+var DefaultPriorityMap = map[string]int{
+	"highest": 0, "blocker": 0, "critical": 0, "urgent": 0, "p0": 0,
+	"high": 1, "major": 1, "p1": 1,
+	"medium": 2, "normal": 2, "p2": 2,
+	"low": 3, "lowest": 3, "minor": 3, "trivial": 3, "p3": 3,
+}
+
+// Manager imports issues and pushes bead changes back. Every method is a
+// no-op on a nil Manager.
+type Manager struct {
+	store      Store
+	beads      Beads
+	cfg        Config
+	priorities map[string]int
+
+	mu       sync.Mutex
+	projects map[string]*Project
+	lastSync map[string]time.Time // By project
+	applyMu  sync.Mutex           // Serialises imports, which may come from polls and webhooks at once
+	stop     chan struct{}
+}
+
+// NewManager creates a manager backed by store that files beads through
+// beads
+func NewManager(store Store, beads Beads, cfg Config) *Manager {
+	if store == nil || beads == nil {
+		return nil
+	}
+	priorities := make(map[string]int, len(DefaultPriorityMap)+len(cfg.PriorityMap))
+	for name, p := range DefaultPriorityMap {
+		priorities[name] = p
+	}
+	for name, p := range cfg.PriorityMap {
+		priorities[strings.ToLower(name)] = p
+	}
+	return &Manager{
+		store:      store,
+		beads:      beads,
+		cfg:        cfg,
+		priorities: priorities,
+		projects:   make(map[string]*Project),
+		lastSync:   make(map[string]time.Time),
+	}
+}
+
+// Enabled reports whether beads are synced
+func (m *Manager) Enabled() bool {
+	return m != nil && m.cfg.Enabled
+}
+
+// AddProject syncs a project with its tracker
+func (m *Manager) AddProject(p Project) error {
+	if m == nil {
+		return nil
+	}
+	switch p.Tracker {
+	case TrackerGitHub, TrackerJira:
+	default:
+		return fmt.Errorf("unknown issue tracker %q", p.Tracker)
+	}
+	if p.ProjectID == "" || p.Ref == "" || p.Client == nil {
+		return fmt.Errorf("project, reference and client are required")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.projects[p.ProjectID] = &p
+	return nil
+}
+
+// Project returns the tracker a project syncs with, or nil
+func (m *Manager) Project(projectID string) *Project {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.projects[projectID]
+}
+
+// ProjectFor returns the project syncing with ref on tracker, a GitHub
+// owner/name or a Jira project key, or "" if none does
+func (m *Manager) ProjectFor(tracker, ref string) string {
+	if m == nil {
+		return ""
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, p := range m.projects {
+		if p.Tracker == tracker && strings.EqualFold(p.Ref, ref) {
+			return p.ProjectID
+		}
+	}
+	return ""
+}
+
+// Links returns a project's links, most recently synced first
+func (m *Manager) Links(projectID string) ([]*Link, error) {
+	if m == nil {
+		return nil, nil
+	}
+	return m.store.ListIssueLinks(projectID)
+}
+
+// Sync imports the issues of a project updated since its last sync
+func (m *Manager) Sync(ctx context.Context, projectID string) (*Result, error) {
+	if !m.Enabled() {
+		return nil, fmt.Errorf("issue sync is disabled")
+	}
+	p := m.Project(projectID)
+	if p == nil {
+		return nil, fmt.Errorf("project %s does not sync with an issue tracker", projectID)
+	}
+	m.mu.Lock()
+	since := m.lastSync[projectID]
+	m.mu.Unlock()
+	started := time.Now()
+
+	issues, err := p.Client.Issues(ctx, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s issues: %w", p.Tracker, err)
+	}
+	res := &Result{ProjectID: projectID}
+	for _, issue := range issues {
+		_, outcome, err := m.Apply(ctx, projectID, issue)
+		switch {
+		case err != nil:
+			res.Errors = append(res.Errors, fmt.Sprintf("%s: %v", issue.Key, err))
+		case outcome == OutcomeImported:
+			res.Imported++
+		case outcome == OutcomeUpdated:
+			res.Updated++
+		default:
+			res.Unchanged++
+		}
+	}
+	if len(res.Errors) == 0 {
+		m.mu.Lock()
+		m.lastSync[projectID] = started
+		m.mu.Unlock()
+	}
+	return res, nil
+}
+
+// Apply brings the bead of an issue in line with it, filing a bead for an
+// open issue that has none. Closed issues and issues without one of the
+// project's labels are not imported. It returns the issue's link, nil
+// when the issue is skipped, and the outcome.
+func (m *Manager) Apply(ctx context.Context, projectID string, issue Issue) (*Link, string, error) {
+	if !m.Enabled() {
+		return nil, OutcomeSkipped, nil
+	}
+	p := m.Project(projectID)
+	if p == nil {
+		return nil, "", fmt.Errorf("project %s does not sync with an issue tracker", projectID)
+	}
+	if issue.Key == "" {
+		return nil, "", fmt.Errorf("issue has no key")
+	}
+	if issue.State == "" {
+		issue.State = StateOpen
+	}
+
+	m.applyMu.Lock()
+	defer m.applyMu.Unlock()
+	link, err := m.store.GetIssueLink(projectID, p.Tracker, issue.Key)
+	if err != nil {
+		return nil, "", err
+	}
+	fields := m.fields(p, issue)
+	now := time.Now()
+
+	if link == nil {
+		if issue.State == StateClosed || !hasLabel(issue.Labels, p.Labels) {
+			return nil, OutcomeSkipped, nil
+		}
+		beadID, err := m.beads.CreateBead(projectID, fields)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to file bead: %w", err)
+		}
+		link = &Link{
+			ProjectID:      projectID,
+			Tracker:        p.Tracker,
+			ExternalID:     issue.Key,
+			BeadID:         beadID,
+			URL:            issue.URL,
+			IssueState:     issue.State,
+			IssueUpdatedAt: issue.UpdatedAt,
+			SyncedAt:       now,
+		}
+		if err := m.store.SaveIssueLink(link); err != nil {
+			return nil, "", err
+		}
+		return link, OutcomeImported, nil
+	}
+
+	if !issue.UpdatedAt.After(link.IssueUpdatedAt) && issue.State == link.IssueState {
+		return link, OutcomeUnchanged, nil
+	}
+	// Closing or reopening the issue closes or reopens the bead; otherwise
+	// the bead's status is Loom's to manage
+	fields.Status = ""
+	if issue.State != link.IssueState {
+		if issue.State == StateClosed {
+			fields.Status = models.BeadStatusClosed
+		} else if link.IssueState == StateClosed {
+			fields.Status = models.BeadStatusOpen
+		}
+	}
+	// The link is saved first so the bead's status change is not pushed
+	// back to the issue
+	link.URL = issue.URL
+	link.IssueState = issue.State
+	if issue.UpdatedAt.After(link.IssueUpdatedAt) {
+		link.IssueUpdatedAt = issue.UpdatedAt
+	}
+	link.SyncedAt = now
+	if err := m.store.SaveIssueLink(link); err != nil {
+		return nil, "", err
+	}
+	if err := m.beads.UpdateBead(link.BeadID, fields); err != nil {
+		return nil, "", fmt.Errorf("failed to update bead %s: %w", link.BeadID, err)
+	}
+	return link, OutcomeUpdated, nil
+}
+
+// IssueCommented adds a comment made on an issue to its bead. Comments
+// Loom pushed itself are skipped.
+func (m *Manager) IssueCommented(projectID, key, author, body string) error {
+	if !m.Enabled() || strings.HasPrefix(body, CommentPrefix) {
+		return nil
+	}
+	p := m.Project(projectID)
+	if p == nil {
+		return fmt.Errorf("project %s does not sync with an issue tracker", projectID)
+	}
+	link, err := m.store.GetIssueLink(projectID, p.Tracker, key)
+	if err != nil || link == nil {
+		return err
+	}
+	return m.beads.AddComment(link.BeadID, p.Tracker+":"+author, body)
+}
+
+// BeadChanged pushes a bead's new status to its issue, if it was imported
+// and the issue is not in that state already
+func (m *Manager) BeadChanged(ctx context.Context, beadID string, status models.BeadStatus) error {
+	link, p, err := m.linkOf(beadID)
+	if err != nil || link == nil {
+		return err
+	}
+	state := IssueState(status)
+	if state == link.IssueState {
+		return nil
+	}
+	if err := p.Client.SetState(ctx, link.ExternalID, state); err != nil {
+		return fmt.Errorf("failed to move %s to %s: %w", link.ExternalID, state, err)
+	}
+	link.IssueState = state
+	link.SyncedAt = time.Now()
+	return m.store.SaveIssueLink(link)
+}
+
+// BeadCommented pushes a comment made on a bead to its issue, if it was
+// imported
+func (m *Manager) BeadCommented(ctx context.Context, beadID, author, body string) error {
+	link, p, err := m.linkOf(beadID)
+	if err != nil || link == nil {
+		return err
+	}
+	return p.Client.Comment(ctx, link.ExternalID, fmt.Sprintf("%s%s: %s", CommentPrefix, author, body))
+}
+
+// SyncAll syncs every project, logging failures
+func (m *Manager) SyncAll(ctx context.Context) {
+	if !m.Enabled() {
+		return
+	}
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.projects))
+	for id := range m.projects {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return
+		}
+		res, err := m.Sync(ctx, id)
+		if err != nil {
+			log.Printf("[IssueSync] Failed to sync %s: %v", id, err)
+			continue
+		}
+		for _, e := range res.Errors {
+			log.Printf("[IssueSync] Failed to sync an issue of %s: %s", id, e)
+		}
+	}
+}
+
+// Start syncs every project now and then every Interval until ctx is done
+// or Close is called. Without an interval it only syncs once.
+func (m *Manager) Start(ctx context.Context) {
+	if !m.Enabled() {
+		return
+	}
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		m.SyncAll(ctx)
+		if m.cfg.Interval <= 0 {
+			return
+		}
+		ticker := time.NewTicker(m.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				m.SyncAll(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops syncing
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// linkOf returns the link of a bead and the tracker of its project
+func (m *Manager) linkOf(beadID string) (*Link, *Project, error) {
+	if !m.Enabled() || beadID == "" {
+		return nil, nil, nil
+	}
+	link, err := m.store.GetIssueLinkByBead(beadID)
+	if err != nil || link == nil {
+		return nil, nil, err
+	}
+	p := m.Project(link.ProjectID)
+	if p == nil || p.Tracker != link.Tracker {
+		return nil, nil, nil
+	}
+	return link, p, nil
+}
+
+// fields maps an issue onto a bead: bug labels make bugs, labels become
+// tags and the priority comes from the tracker's priority or a priority
+// label
+func (m *Manager) fields(p *Project, issue Issue) Fields {
+	f := Fields{
+		Title:       issue.Title,
+		Description: issue.Body,
+		Status:      models.BeadStatusOpen,
+		Priority:    m.Priority(issue),
+		Type:        "task",
+		Tags:        slices.Clone(issue.Labels),
+		Context: map[string]string{
+			ContextTracker:    p.Tracker,
+			ContextExternalID: issue.Key,
+			ContextURL:        issue.URL,
+		},
+	}
+	for _, l := range issue.Labels {
+		if strings.EqualFold(l, "bug") {
+			f.Type = "bug"
+		}
+	}
+	return f
+}
+
+// Priority returns the bead priority of an issue: that of its tracker
+// priority if mapped, else of its first mapped label, else P2
+func (m *Manager) Priority(issue Issue) models.BeadPriority {
+	if p, ok := m.priorities[strings.ToLower(issue.Priority)]; ok {
+		return models.BeadPriority(p)
+	}
+	for _, l := range issue.Labels {
+		name := strings.ToLower(l)
+		for _, prefix := range []string{"priority:", "priority/", "priority-"} {
+			name = strings.TrimSpace(strings.TrimPrefix(name, prefix))
+		}
+		if p, ok := m.priorities[name]; ok {
+			return models.BeadPriority(p)
+		}
+	}
+	return models.BeadPriorityP2
+}
+
+// IssueState returns the issue state a bead status maps to
+func IssueState(status models.BeadStatus) string {
+	switch status {
+	case models.BeadStatusClosed:
+		return StateClosed
+	case models.BeadStatusInProgress:
+		return StateInProgress
+	default:
+		return StateOpen
+	}
+}
+
+// GitHubState returns the state of a GitHub issue state, open or closed
+func GitHubState(state string) string {
+	if strings.EqualFold(state, "closed") {
+		return StateClosed
+	}
+	return StateOpen
+}
+
+// hasLabel reports whether labels holds one of want, or want is empty
+func hasLabel(labels, want []string) bool {
+	if len(want) == 0 {
+		return true
+	}
+	for _, l := range labels {
+		for _, w := range want {
+			if strings.EqualFold(l, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
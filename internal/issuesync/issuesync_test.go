@@ -0,0 +1,233 @@
+package issuesync_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/issuesync"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// fakeTracker serves fixed issues and records what was pushed to it
+type fakeTracker struct {
+	issues   []issuesync.Issue
+	states   map[string]string
+	comments []string
+}
+
+func (f *fakeTracker) Issues(ctx context.Context, since time.Time) ([]issuesync.Issue, error) {
+	var out []issuesync.Issue
+	for _, i := range f.issues {
+		if i.UpdatedAt.After(since) {
+			out = append(out, i)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeTracker) SetState(ctx context.Context, key, state string) error {
+	f.states[key] = state
+	return nil
+}
+
+func (f *fakeTracker) Comment(ctx context.Context, key, body string) error {
+	f.comments = append(f.comments, key+":"+body)
+	return nil
+}
+
+// fakeBeads keeps beads in a map
+type fakeBeads struct {
+	beads    map[string]*issuesync.Fields
+	comments []string
+}
+
+func (f *fakeBeads) CreateBead(projectID string, fl issuesync.Fields) (string, error) {
+	id := "bd-" + fl.Context[issuesync.ContextExternalID]
+	f.beads[id] = &fl
+	return id, nil
+}
+
+func (f *fakeBeads) UpdateBead(beadID string, fl issuesync.Fields) error {
+	b := f.beads[beadID]
+	status := b.Status
+	*b = fl
+	if fl.Status == "" {
+		b.Status = status
+	}
+	return nil
+}
+
+func (f *fakeBeads) AddComment(beadID, author, body string) error {
+	f.comments = append(f.comments, beadID+":"+author+":"+body)
+	return nil
+}
+
+func newTestManager(t *testing.T, labels ...string) (*issuesync.Manager, *fakeTracker, *fakeBeads) {
+	t.Helper()
+	tracker := &fakeTracker{states: make(map[string]string)}
+	beads := &fakeBeads{beads: make(map[string]*issuesync.Fields)}
+	db, err := database.New(filepath.Join(t.TempDir(), "issuesync.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m := issuesync.NewManager(db, beads, issuesync.Config{Enabled: true, PriorityMap: map[string]int{"sev1": 0}})
+	if err := m.AddProject(issuesync.Project{ProjectID: "proj-1", Tracker: issuesync.TrackerGitHub, Ref: "acme/widgets", Labels: labels, Client: tracker}); err != nil {
+		t.Fatalf("AddProject failed: %v", err)
+	}
+	return m, tracker, beads
+}
+
+func TestSync_ImportsAndUpdatesIssues(t *testing.T) {
+	m, tracker, beads := newTestManager(t)
+	ctx := context.Background()
+	t0 := time.Now().Add(-time.Hour)
+	tracker.issues = []issuesync.Issue{
+		{Key: "1", Title: "Login fails", State: issuesync.StateOpen, Labels: []string{"bug", "priority: high"}, UpdatedAt: t0},
+		{Key: "2", Title: "Old", State: issuesync.StateClosed, UpdatedAt: t0},
+	}
+
+	res, err := m.Sync(ctx, "proj-1")
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if res.Imported != 1 || res.Unchanged != 1 {
+		t.Fatalf("expected one import and a skipped closed issue, got %+v", res)
+	}
+	b := beads.beads["bd-1"]
+	if b == nil || b.Type != "bug" || b.Priority != models.BeadPriorityP1 || b.Context[issuesync.ContextTracker] != issuesync.TrackerGitHub {
+		t.Fatalf("unexpected bead %+v", b)
+	}
+
+	// The tracker closes the issue: the bead closes, and nothing is pushed back
+	tracker.issues[0].State = issuesync.StateClosed
+	tracker.issues[0].Title = "Login fails on Safari"
+	tracker.issues[0].UpdatedAt = time.Now()
+	if res, _ = m.Sync(ctx, "proj-1"); res.Updated != 1 {
+		t.Fatalf("expected the issue to be updated, got %+v", res)
+	}
+	if b := beads.beads["bd-1"]; b.Status != models.BeadStatusClosed || b.Title != "Login fails on Safari" {
+		t.Errorf("expected a closed, retitled bead, got %+v", b)
+	}
+	if err := m.BeadChanged(ctx, "bd-1", models.BeadStatusClosed); err != nil || len(tracker.states) != 0 {
+		t.Errorf("the tracker's own change must not be pushed back: %v %v", tracker.states, err)
+	}
+
+	// Reopening the bead reopens the issue
+	if err := m.BeadChanged(ctx, "bd-1", models.BeadStatusInProgress); err != nil {
+		t.Fatalf("BeadChanged failed: %v", err)
+	}
+	if tracker.states["1"] != issuesync.StateInProgress {
+		t.Errorf("expected the issue to be moved, got %v", tracker.states)
+	}
+	links, _ := m.Links("proj-1")
+	if len(links) != 1 || links[0].IssueState != issuesync.StateInProgress {
+		t.Errorf("unexpected links %+v", links)
+	}
+}
+
+func TestApply_LabelsAndPriorities(t *testing.T) {
+	m, _, beads := newTestManager(t, "loom")
+	ctx := context.Background()
+
+	if _, outcome, _ := m.Apply(ctx, "proj-1", issuesync.Issue{Key: "3", Title: "Unlabelled"}); outcome != issuesync.OutcomeSkipped {
+		t.Errorf("expected an issue without the project's label to be skipped, got %s", outcome)
+	}
+	if _, outcome, _ := m.Apply(ctx, "proj-1", issuesync.Issue{Key: "4", Title: "Outage", Priority: "SEV1", Labels: []string{"Loom"}}); outcome != issuesync.OutcomeImported {
+		t.Fatalf("expected a labelled issue to be imported, got %s", outcome)
+	}
+	if b := beads.beads["bd-4"]; b.Priority != models.BeadPriorityP0 || b.Type != "task" {
+		t.Errorf("expected a P0 task from the configured priority, got %+v", b)
+	}
+	if p := m.Priority(issuesync.Issue{Labels: []string{"wontfix"}}); p != models.BeadPriorityP2 {
+		t.Errorf("expected unmapped issues to be P2, got %d", p)
+	}
+}
+
+func TestComments(t *testing.T) {
+	m, tracker, beads := newTestManager(t)
+	ctx := context.Background()
+	if _, _, err := m.Apply(ctx, "proj-1", issuesync.Issue{Key: "5", Title: "Docs"}); err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if err := m.BeadCommented(ctx, "bd-5", "coder", "Fixed in #6"); err != nil {
+		t.Fatalf("BeadCommented failed: %v", err)
+	}
+	if len(tracker.comments) != 1 || tracker.comments[0] != "5:[loom] coder: Fixed in #6" {
+		t.Fatalf("unexpected pushed comments %v", tracker.comments)
+	}
+
+	_ = m.IssueCommented("proj-1", "5", "octocat", "[loom] coder: Fixed in #6")
+	_ = m.IssueCommented("proj-1", "5", "octocat", "Thanks!")
+	_ = m.IssueCommented("proj-1", "99", "octocat", "Not imported")
+	if len(beads.comments) != 1 || beads.comments[0] != "bd-5:github:octocat:Thanks!" {
+		t.Errorf("expected only the human comment to be imported, got %v", beads.comments)
+	}
+	if err := m.BeadCommented(ctx, "bd-other", "coder", "x"); err != nil || len(tracker.comments) != 1 {
+		t.Errorf("comments on beads without an issue stay local: %v", err)
+	}
+}
+
+func TestJiraClient(t *testing.T) {
+	var transitioned, commented string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "bot@example.com" || pass != "token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.URL.Path == "/rest/api/2/search":
+			if !strings.Contains(r.URL.Query().Get("jql"), `project = "OPS"`) {
+				t.Errorf("unexpected JQL %s", r.URL.Query().Get("jql"))
+			}
+			_, _ = w.Write([]byte(`{"total": 1, "issues": [{"key": "OPS-7", "fields": {
+				"summary": "Disk full", "status": {"name": "In Progress", "statusCategory": {"key": "indeterminate"}},
+				"priority": {"name": "Highest"}, "labels": ["infra"], "updated": "2026-01-02T15:04:05.000+0000"}}]}`))
+		case r.URL.Path == "/rest/api/2/issue/OPS-7/transitions" && r.Method == http.MethodGet:
+			_, _ = w.Write([]byte(`{"transitions": [{"id": "11", "to": {"statusCategory": {"key": "new"}}}, {"id": "31", "to": {"statusCategory": {"key": "done"}}}]}`))
+		case r.URL.Path == "/rest/api/2/issue/OPS-7/transitions":
+			var body struct {
+				Transition struct{ ID string } `json:"transition"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			transitioned = body.Transition.ID
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/rest/api/2/issue/OPS-7/comment":
+			var body struct{ Body string }
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			commented = body.Body
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := issuesync.NewJiraClient(srv.URL+"/", "bot@example.com", "token", "OPS")
+	ctx := context.Background()
+	issues, err := c.Issues(ctx, time.Time{})
+	if err != nil {
+		t.Fatalf("Issues failed: %v", err)
+	}
+	if len(issues) != 1 || issues[0].State != issuesync.StateInProgress || issues[0].Priority != "Highest" || issues[0].URL != srv.URL+"/browse/OPS-7" || issues[0].UpdatedAt.IsZero() {
+		t.Fatalf("unexpected issues %+v", issues)
+	}
+	if err := c.SetState(ctx, "OPS-7", issuesync.StateClosed); err != nil || transitioned != "31" {
+		t.Errorf("expected the done transition, got %q, %v", transitioned, err)
+	}
+	if err := c.SetState(ctx, "OPS-7", issuesync.StateInProgress); err == nil {
+		t.Error("expected a missing transition to fail")
+	}
+	if err := c.Comment(ctx, "OPS-7", "hello"); err != nil || commented != "hello" {
+		t.Errorf("Comment = %q, %v", commented, err)
+	}
+}
@@ -0,0 +1,220 @@
+package issuesync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// jiraTimeLayout is how Jira's REST API writes times
+const jiraTimeLayout = "2006-01-02T15:04:05.000-0700"
+
+// jiraPageSize is the most issues asked for per search
+const jiraPageSize = 100
+
+// JiraIssue is an issue as Jira's REST API and webhooks send it
+type JiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      struct {
+			Name           string `json:"name"`
+			StatusCategory struct {
+				Key string `json:"key"` // new, indeterminate or done
+			} `json:"statusCategory"`
+		} `json:"status"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Labels  []string `json:"labels"`
+		Project struct {
+			Key string `json:"key"`
+		} `json:"project"`
+		Updated string `json:"updated"`
+	} `json:"fields"`
+}
+
+// Issue converts a Jira issue, linking it under baseURL
+func (j *JiraIssue) Issue(baseURL string) Issue {
+	issue := Issue{
+		Key:    j.Key,
+		Title:  j.Fields.Summary,
+		Body:   j.Fields.Description,
+		State:  JiraState(j.Fields.Status.StatusCategory.Key),
+		Labels: j.Fields.Labels,
+	}
+	if baseURL != "" {
+		issue.URL = strings.TrimRight(baseURL, "/") + "/browse/" + j.Key
+	}
+	if j.Fields.Priority != nil {
+		issue.Priority = j.Fields.Priority.Name
+	}
+	if t, err := time.Parse(jiraTimeLayout, j.Fields.Updated); err == nil {
+		issue.UpdatedAt = t
+	}
+	return issue
+}
+
+// JiraState returns the state of a Jira status category
+func JiraState(category string) string {
+	switch category {
+	case "done":
+		return StateClosed
+	case "indeterminate":
+		return StateInProgress
+	default:
+		return StateOpen
+	}
+}
+
+// jiraCategory is the status category a state moves to
+func jiraCategory(state string) string {
+	switch state {
+	case StateClosed:
+		return "done"
+	case StateInProgress:
+		return "indeterminate"
+	default:
+		return "new"
+	}
+}
+
+// JiraClient reads and writes the issues of a Jira project through its
+// REST API
+type JiraClient struct {
+	baseURL    string
+	email      string
+	token      string
+	projectKey string
+	client     *http.Client
+}
+
+// NewJiraClient creates a client for a Jira project. With an email the
+// token is a Jira Cloud API token; without one it is a personal access
+// token.
+func NewJiraClient(baseURL, email, token, projectKey string) *JiraClient {
+	return &JiraClient{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		email:      email,
+		token:      token,
+		projectKey: projectKey,
+		client:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// BaseURL returns the Jira site the client talks to
+func (c *JiraClient) BaseURL() string {
+	return c.baseURL
+}
+
+// Issues returns the project's issues updated since, oldest change first
+func (c *JiraClient) Issues(ctx context.Context, since time.Time) ([]Issue, error) {
+	jql := fmt.Sprintf("project = %q", c.projectKey)
+	if !since.IsZero() {
+		// JQL has minute precision, so a minute of overlap is re-read
+		jql += fmt.Sprintf(" AND updated >= %q", since.Add(-time.Minute).Format("2006-01-02 15:04"))
+	}
+	jql += " ORDER BY updated ASC"
+
+	var issues []Issue
+	for start := 0; ; start += jiraPageSize {
+		q := url.Values{}
+		q.Set("jql", jql)
+		q.Set("fields", "summary,description,status,priority,labels,project,updated")
+		q.Set("startAt", fmt.Sprint(start))
+		q.Set("maxResults", fmt.Sprint(jiraPageSize))
+		var page struct {
+			Total  int         `json:"total"`
+			Issues []JiraIssue `json:"issues"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/rest/api/2/search?"+q.Encode(), nil, &page); err != nil {
+			return nil, err
+		}
+		for i := range page.Issues {
+			issues = append(issues, page.Issues[i].Issue(c.baseURL))
+		}
+		if len(page.Issues) == 0 || start+len(page.Issues) >= page.Total {
+			return issues, nil
+		}
+	}
+}
+
+// SetState transitions an issue to the first status in the state's
+// category that its workflow allows
+func (c *JiraClient) SetState(ctx context.Context, key, state string) error {
+	var body struct {
+		Transitions []struct {
+			ID string `json:"id"`
+			To struct {
+				StatusCategory struct {
+					Key string `json:"key"`
+				} `json:"statusCategory"`
+			} `json:"to"`
+		} `json:"transitions"`
+	}
+	path := "/rest/api/2/issue/" + url.PathEscape(key) + "/transitions"
+	if err := c.do(ctx, http.MethodGet, path, nil, &body); err != nil {
+		return err
+	}
+	want := jiraCategory(state)
+	for _, t := range body.Transitions {
+		if t.To.StatusCategory.Key == want {
+			return c.do(ctx, http.MethodPost, path, map[string]interface{}{"transition": map[string]string{"id": t.ID}}, nil)
+		}
+	}
+	return fmt.Errorf("no transition of %s leads to a %s status", key, want)
+}
+
+// Comment adds a comment to an issue
+func (c *JiraClient) Comment(ctx context.Context, key, body string) error {
+	return c.do(ctx, http.MethodPost, "/rest/api/2/issue/"+url.PathEscape(key)+"/comment", map[string]string{"body": body}, nil)
+}
+
+// do sends a request to the REST API and decodes its answer into out
+func (c *JiraClient) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.email != "" {
+		req.SetBasicAuth(c.email, c.token)
+	} else if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("jira request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("jira returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode jira response: %w", err)
+	}
+	return nil
+}
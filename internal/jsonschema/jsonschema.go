@@ -0,0 +1,167 @@
+// Package jsonschema validates JSON documents against the subset of JSON
+// Schema used to constrain model responses: type, enum, properties,
+// required, additionalProperties and items. Other keywords are ignored, so
+// a document passing here may still fail a stricter validator.
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Schema is a parsed JSON schema
+type Schema struct {
+	Type                 typeList           `json:"type,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *additional        `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+}
+
+// typeList is a schema type, which may be one name or a list of them
+type typeList []string
+
+func (t *typeList) UnmarshalJSON(data []byte) error {
+	var one string
+	if err := json.Unmarshal(data, &one); err == nil {
+		*t = typeList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*t = many
+	return nil
+}
+
+// additional is additionalProperties: false, or a schema extra properties
+// must match
+type additional struct {
+	forbidden bool
+	schema    *Schema
+}
+
+func (a *additional) UnmarshalJSON(data []byte) error {
+	var allowed bool
+	if err := json.Unmarshal(data, &allowed); err == nil {
+		a.forbidden = !allowed
+		return nil
+	}
+	a.schema = &Schema{}
+	return json.Unmarshal(data, a.schema)
+}
+
+// Parse parses a JSON schema
+func Parse(raw []byte) (*Schema, error) {
+	var s Schema
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return &s, nil
+}
+
+// Validate checks a JSON document against the schema, returning one
+// problem per violation, each prefixed with the path to the offending
+// value. A document that is not JSON is a single problem.
+func (s *Schema) Validate(doc []byte) []string {
+	var value interface{}
+	if err := json.Unmarshal(doc, &value); err != nil {
+		return []string{fmt.Sprintf("not valid JSON: %v", err)}
+	}
+	var problems []string
+	s.check("$", value, &problems)
+	return problems
+}
+
+func (s *Schema) check(path string, value interface{}, problems *[]string) {
+	if len(s.Type) > 0 && !s.Type.matches(value) {
+		*problems = append(*problems, fmt.Sprintf("%s: expected %s, got %s", path, strings.Join(s.Type, " or "), typeOf(value)))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(s.Enum, value) {
+		*problems = append(*problems, fmt.Sprintf("%s: %s is not one of %s", path, compact(value), compact(s.Enum)))
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*problems = append(*problems, fmt.Sprintf("%s: missing required property %q", path, name))
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := path + "." + name
+			if prop, ok := s.Properties[name]; ok {
+				prop.check(child, v[name], problems)
+				continue
+			}
+			switch {
+			case s.AdditionalProperties == nil:
+			case s.AdditionalProperties.forbidden:
+				*problems = append(*problems, fmt.Sprintf("%s: unexpected property", child))
+			case s.AdditionalProperties.schema != nil:
+				s.AdditionalProperties.schema.check(child, v[name], problems)
+			}
+		}
+	case []interface{}:
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.check(fmt.Sprintf("%s[%d]", path, i), item, problems)
+			}
+		}
+	}
+}
+
+func (t typeList) matches(value interface{}) bool {
+	actual := typeOf(value)
+	for _, want := range t {
+		if want == actual || (want == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+func inEnum(enum []interface{}, value interface{}) bool {
+	want := compact(value)
+	for _, e := range enum {
+		if compact(e) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func compact(value interface{}) string {
+	data, _ := json.Marshal(value)
+	return string(data)
+}
@@ -0,0 +1,52 @@
+package jsonschema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	schema, err := Parse([]byte(`{
+		"type": "object",
+		"properties": {
+			"action": {"type": "string", "enum": ["read", "write"]},
+			"path": {"type": "string"},
+			"lines": {"type": ["integer", "null"]},
+			"files": {"type": "array", "items": {"type": "string"}}
+		},
+		"required": ["action"],
+		"additionalProperties": false
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string
+	}{
+		{"valid", `{"action": "read", "path": "a.go", "lines": 3, "files": ["x"]}`, nil},
+		{"null allowed", `{"action": "write", "lines": null}`, nil},
+		{"not JSON", `{"action": `, []string{"not valid JSON: unexpected end of JSON input"}},
+		{"wrong root", `["read"]`, []string{"$: expected object, got array"}},
+		{"missing and unexpected", `{"path": 1, "extra": true}`, []string{
+			`$: missing required property "action"`,
+			"$.extra: unexpected property",
+			"$.path: expected string, got integer",
+		}},
+		{"enum and items", `{"action": "delete", "lines": 1.5, "files": ["a", 2]}`, []string{
+			`$.action: "delete" is not one of ["read","write"]`,
+			"$.files[1]: expected string, got integer",
+			"$.lines: expected integer or null, got number",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.Validate([]byte(tt.doc))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate(%s) = %q, want %q", tt.doc, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package keymanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// awsCredentials sign requests to AWS
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// awsKMSWrapper wraps the data key with an AWS KMS key. Credentials are
+// read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN.
+type awsKMSWrapper struct {
+	keyID    string
+	region   string
+	endpoint string
+	creds    awsCredentials
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newAWSKMSWrapper(cfg config.KeyStoreConfig) (*awsKMSWrapper, error) {
+	w := &awsKMSWrapper{
+		keyID:  cfg.KeyID,
+		region: firstNonEmpty(cfg.Region, arnRegion(cfg.KeyID), os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")),
+		creds: awsCredentials{
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		client: &http.Client{Timeout: wrapTimeout},
+		now:    time.Now,
+	}
+	switch {
+	case w.keyID == "":
+		return nil, errors.New("awskms: security.key_store.key_id names no KMS key")
+	case w.region == "":
+		return nil, errors.New("awskms: set security.key_store.region or AWS_REGION")
+	case w.creds.AccessKeyID == "" || w.creds.SecretAccessKey == "":
+		return nil, errors.New("awskms: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	w.endpoint = strings.TrimSuffix(firstNonEmpty(cfg.Address, "https://kms."+w.region+".amazonaws.com"), "/")
+	return w, nil
+}
+
+// arnRegion returns the region of a KMS key or alias ARN
+func arnRegion(keyID string) string {
+	parts := strings.Split(keyID, ":")
+	if len(parts) >= 6 && parts[0] == "arn" && parts[2] == "kms" {
+		return parts[3]
+	}
+	return ""
+}
+
+func (w *awsKMSWrapper) Backend() string { return BackendAWSKMS }
+func (w *awsKMSWrapper) KeyID() string   { return w.keyID }
+
+func (w *awsKMSWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var resp struct {
+		CiphertextBlob []byte `json:"CiphertextBlob"`
+	}
+	in := map[string]interface{}{"KeyId": w.keyID, "Plaintext": dataKey}
+	if err := w.call(ctx, "Encrypt", in, &resp); err != nil {
+		return nil, fmt.Errorf("kms encrypt: %w", err)
+	}
+	return resp.CiphertextBlob, nil
+}
+
+func (w *awsKMSWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"Plaintext"`
+	}
+	in := map[string]interface{}{"KeyId": keyID, "CiphertextBlob": wrapped}
+	if err := w.call(ctx, "Decrypt", in, &resp); err != nil {
+		return nil, fmt.Errorf("kms decrypt: %w", err)
+	}
+	return resp.Plaintext, nil
+}
+
+// call sends a KMS JSON API request signed with Signature Version 4. Byte
+// slices travel base64-encoded, as encoding/json encodes them.
+func (w *awsKMSWrapper) call(ctx context.Context, op string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "TrentService."+op)
+	signV4(req, body, "kms", w.region, w.creds, w.now())
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req,
+// signing the host and every header already set
+func signV4(req *http.Request, body []byte, service, region string, creds awsCredentials, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, k := range names {
+		canonicalHeaders.WriteString(k + ":" + headers[k] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
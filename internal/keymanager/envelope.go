@@ -0,0 +1,274 @@
+package keymanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// Backends that can wrap the data key
+const (
+	BackendPassword = "password"
+	BackendVault    = "vault"
+	BackendAWSKMS   = "awskms"
+	BackendGCPKMS   = "gcpkms"
+)
+
+// wrapTimeout bounds a call to a KMS backend
+const wrapTimeout = 30 * time.Second
+
+// KeyWrapper encrypts the data key that encrypts stored credentials. The
+// wrapped data key is saved in the store file; the key that wraps it stays
+// in the backend.
+type KeyWrapper interface {
+	Backend() string
+	KeyID() string // The key encryption key new data keys are wrapped with
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped with keyID, which may be a key
+	// other than KeyID
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// Envelope records how a store's data key is wrapped
+type Envelope struct {
+	Backend    string `json:"backend"`
+	KeyID      string `json:"key_id,omitempty"`
+	WrappedKey string `json:"wrapped_key"` // Base64
+}
+
+// NewWrapper returns the KeyWrapper for cfg, or nil for the password
+func NewWrapper(cfg config.KeyStoreConfig) (KeyWrapper, error) {
+	switch cfg.Backend {
+	case "", BackendPassword:
+		return nil, nil
+	case BackendVault:
+		return newVaultWrapper(cfg)
+	case BackendAWSKMS:
+		return newAWSKMSWrapper(cfg)
+	case BackendGCPKMS:
+		return newGCPKMSWrapper(cfg)
+	}
+	return nil, fmt.Errorf("unknown key store backend %q", cfg.Backend)
+}
+
+// SetWrapper chooses what wraps the data key; nil means the password. It
+// takes effect at the next Unlock, which rewraps a data key wrapped by the
+// password or by another key of the same backend.
+func (km *KeyManager) SetWrapper(w KeyWrapper) {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+	km.wrapper = w
+}
+
+// KeyEncryption reports how the data key is wrapped, or nil before the
+// store is first unlocked
+func (km *KeyManager) KeyEncryption() *Envelope {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+	if km.store.KeyEncryption == nil {
+		return nil
+	}
+	env := *km.store.KeyEncryption
+	env.WrappedKey = ""
+	return &env
+}
+
+// keyWrapper is the configured wrapper, or the password
+func (km *KeyManager) keyWrapper() KeyWrapper {
+	if km.wrapper != nil {
+		return km.wrapper
+	}
+	return passwordWrapper{password: km.password}
+}
+
+// newDataKey generates the data key of a new store and wraps it
+func (km *KeyManager) newDataKey() error {
+	km.dataKey = make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, km.dataKey); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), wrapTimeout)
+	defer cancel()
+	return km.wrapDataKey(ctx, km.keyWrapper())
+}
+
+// openDataKey unwraps the data key of a loaded store. A version 1.0 store
+// is upgraded, and a data key wrapped other than as configured is rewrapped.
+func (km *KeyManager) openDataKey() error {
+	ctx, cancel := context.WithTimeout(context.Background(), wrapTimeout)
+	defer cancel()
+	want := km.keyWrapper()
+
+	env := km.store.KeyEncryption
+	if env == nil {
+		return km.upgradeStore(ctx, want)
+	}
+	from := want
+	if env.Backend != want.Backend() {
+		if env.Backend != BackendPassword {
+			return fmt.Errorf("key store data key is wrapped by %s; set security.key_store.backend to %s to unlock it", env.Backend, env.Backend)
+		}
+		from = passwordWrapper{password: km.password}
+	}
+	wrapped, err := base64.StdEncoding.DecodeString(env.WrappedKey)
+	if err != nil {
+		return fmt.Errorf("failed to decode data key: %w", err)
+	}
+	dataKey, err := from.UnwrapKey(ctx, env.KeyID, wrapped)
+	if err != nil {
+		return fmt.Errorf("failed to unwrap data key with %s: %w", env.Backend, err)
+	}
+	km.dataKey = dataKey
+
+	if env.Backend == want.Backend() && env.KeyID == want.KeyID() {
+		return nil
+	}
+	if err := km.wrapDataKey(ctx, want); err != nil {
+		return fmt.Errorf("failed to rewrap data key with %s: %w", want.Backend(), err)
+	}
+	return km.saveStore()
+}
+
+// upgradeStore moves a version 1.0 store, whose keys are each encrypted
+// with the password, to a wrapped data key
+func (km *KeyManager) upgradeStore(ctx context.Context, w KeyWrapper) error {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return err
+	}
+	upgraded := make(map[string]string, len(km.store.Keys))
+	for id, entry := range km.store.Keys {
+		data, err := base64.StdEncoding.DecodeString(entry.EncryptedData)
+		if err != nil {
+			return fmt.Errorf("failed to decode key %s: %w", id, err)
+		}
+		plaintext, err := passwordDecrypt(km.password, data)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt key %s: %w", id, err)
+		}
+		sealed, err := seal(dataKey, plaintext)
+		if err != nil {
+			return err
+		}
+		upgraded[id] = base64.StdEncoding.EncodeToString(sealed)
+	}
+
+	km.dataKey = dataKey
+	if err := km.wrapDataKey(ctx, w); err != nil {
+		return fmt.Errorf("failed to wrap data key with %s: %w", w.Backend(), err)
+	}
+	for id, data := range upgraded {
+		km.store.Keys[id].EncryptedData = data
+	}
+	return km.saveStore()
+}
+
+// wrapDataKey wraps the data key with w and records it in the store
+func (km *KeyManager) wrapDataKey(ctx context.Context, w KeyWrapper) error {
+	wrapped, err := w.WrapKey(ctx, km.dataKey)
+	if err != nil {
+		return err
+	}
+	km.store.Version = storeVersion
+	km.store.KeyEncryption = &Envelope{
+		Backend:    w.Backend(),
+		KeyID:      w.KeyID(),
+		WrappedKey: base64.StdEncoding.EncodeToString(wrapped),
+	}
+	return nil
+}
+
+// seal encrypts plaintext with the data key using AES-GCM, prefixing the
+// nonce
+func seal(dataKey, plaintext []byte) ([]byte, error) {
+	if len(dataKey) != keySize {
+		return nil, errors.New("key store is locked")
+	}
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// unseal decrypts data from seal
+func unseal(dataKey, data []byte) ([]byte, error) {
+	gcm, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("invalid encrypted data")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// passwordWrapper wraps the data key with a key derived from the master
+// password, as every key was encrypted before envelope encryption
+type passwordWrapper struct {
+	password []byte
+}
+
+func (p passwordWrapper) Backend() string { return BackendPassword }
+func (p passwordWrapper) KeyID() string   { return "" }
+
+func (p passwordWrapper) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	return passwordEncrypt(p.password, dataKey)
+}
+
+func (p passwordWrapper) UnwrapKey(_ context.Context, _ string, wrapped []byte) ([]byte, error) {
+	return passwordDecrypt(p.password, wrapped)
+}
+
+// postJSON sends in as JSON to url and decodes the response into out,
+// returning the response body in the error when the status is not 2xx
+func postJSON(ctx context.Context, client *http.Client, url string, header http.Header, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("%s: %s: %s", url, resp.Status, bytes.TrimSpace(data))
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,316 @@
+package keymanager
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// fakeWrapper wraps by prefixing the key ID, so unwrapping with the wrong
+// key fails
+type fakeWrapper struct {
+	backend, keyID string
+}
+
+func (f fakeWrapper) Backend() string { return f.backend }
+func (f fakeWrapper) KeyID() string   { return f.keyID }
+
+func (f fakeWrapper) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	return append([]byte(f.keyID+":"), dataKey...), nil
+}
+
+func (f fakeWrapper) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	prefix := []byte(keyID + ":")
+	if !bytes.HasPrefix(wrapped, prefix) {
+		return nil, errors.New("wrong key")
+	}
+	return wrapped[len(prefix):], nil
+}
+
+func readStore(t *testing.T, path string) *KeyStore {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var store KeyStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		t.Fatal(err)
+	}
+	return &store
+}
+
+func TestKeyManager_UpgradeVersion1(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "keys.json")
+	password := "pw"
+
+	// Build a 1.0 store: salt and verify hash, each key encrypted with the
+	// password
+	km := NewKeyManager(storePath)
+	km.password = []byte(password)
+	km.store.Version = "1.0"
+	if err := km.initializePasswordSalt(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := passwordEncrypt(km.password, []byte("sk-old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	km.store.Keys["openai"] = &KeyEntry{ID: "openai", EncryptedData: base64.StdEncoding.EncodeToString(data)}
+	if err := km.saveStore(); err != nil {
+		t.Fatal(err)
+	}
+
+	km = NewKeyManager(storePath)
+	if err := km.Unlock(password); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if got, err := km.GetKey("openai"); err != nil || got != "sk-old" {
+		t.Fatalf("GetKey = %q, %v", got, err)
+	}
+	store := readStore(t, storePath)
+	if store.Version != storeVersion || store.KeyEncryption == nil || store.KeyEncryption.Backend != BackendPassword {
+		t.Fatalf("store not upgraded: version %s, envelope %+v", store.Version, store.KeyEncryption)
+	}
+
+	// The upgraded store opens again
+	km = NewKeyManager(storePath)
+	if err := km.Unlock(password); err != nil {
+		t.Fatalf("Unlock upgraded store: %v", err)
+	}
+	if got, _ := km.GetKey("openai"); got != "sk-old" {
+		t.Errorf("GetKey after upgrade = %q", got)
+	}
+}
+
+func TestKeyManager_Rewrap(t *testing.T) {
+	storePath := filepath.Join(t.TempDir(), "keys.json")
+	km := NewKeyManager(storePath)
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := km.StoreKey("k", "K", "", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Password-wrapped store moves to a KMS
+	km = NewKeyManager(storePath)
+	km.SetWrapper(fakeWrapper{backend: BackendVault, keyID: "one"})
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatalf("Unlock with vault: %v", err)
+	}
+	if env := km.KeyEncryption(); env == nil || env.Backend != BackendVault || env.KeyID != "one" || env.WrappedKey != "" {
+		t.Fatalf("KeyEncryption = %+v", env)
+	}
+	if got, _ := km.GetKey("k"); got != "value" {
+		t.Fatalf("GetKey = %q", got)
+	}
+
+	// Rotating to another key of the same backend rewraps
+	km = NewKeyManager(storePath)
+	km.SetWrapper(fakeWrapper{backend: BackendVault, keyID: "two"})
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatalf("Unlock with new key: %v", err)
+	}
+	if env := readStore(t, storePath).KeyEncryption; env.KeyID != "two" {
+		t.Errorf("data key wrapped with %q, want two", env.KeyID)
+	}
+
+	// Another backend, or the password, cannot unwrap it
+	for _, w := range []KeyWrapper{fakeWrapper{backend: BackendAWSKMS, keyID: "two"}, nil} {
+		km = NewKeyManager(storePath)
+		km.SetWrapper(w)
+		if err := km.Unlock("pw"); err == nil || !strings.Contains(err.Error(), "wrapped by vault") {
+			t.Errorf("Unlock with %v: err = %v", w, err)
+		}
+		if km.IsUnlocked() {
+			t.Error("key store unlocked with the wrong backend")
+		}
+	}
+}
+
+func TestVaultWrapper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "root" {
+			http.Error(w, "permission denied", http.StatusForbidden)
+			return
+		}
+		var in map[string]string
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.URL.Path {
+		case "/v1/transit/encrypt/loom":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"ciphertext": "vault:v1:" + in["plaintext"]}})
+		case "/v1/transit/decrypt/loom":
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]string{"plaintext": strings.TrimPrefix(in["ciphertext"], "vault:v1:")}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("VAULT_TOKEN", "root")
+	w, err := NewWrapper(config.KeyStoreConfig{Backend: BackendVault, KeyID: "loom", Address: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWrapperRoundTrip(t, w)
+
+	t.Setenv("VAULT_TOKEN", "")
+	if _, err := NewWrapper(config.KeyStoreConfig{Backend: BackendVault, KeyID: "loom", Address: srv.URL}); err == nil {
+		t.Error("expected an error without VAULT_TOKEN")
+	}
+}
+
+func TestAWSKMSWrapper(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/kms/aws4_request") {
+			http.Error(w, "bad signature: "+auth, http.StatusForbidden)
+			return
+		}
+		var in map[string][]byte
+		json.NewDecoder(r.Body).Decode(&in)
+		switch r.Header.Get("X-Amz-Target") {
+		case "TrentService.Encrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"CiphertextBlob": append([]byte("kms"), in["Plaintext"]...)})
+		case "TrentService.Decrypt":
+			json.NewEncoder(w).Encode(map[string][]byte{"Plaintext": bytes.TrimPrefix(in["CiphertextBlob"], []byte("kms"))})
+		default:
+			http.Error(w, "unknown target", http.StatusBadRequest)
+		}
+	}))
+	defer srv.Close()
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	w, err := NewWrapper(config.KeyStoreConfig{
+		Backend: BackendAWSKMS,
+		KeyID:   "arn:aws:kms:eu-west-1:111122223333:key/1234",
+		Address: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWrapperRoundTrip(t, w)
+}
+
+func TestSignV4(t *testing.T) {
+	// get-vanilla from the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, "service", "us-east-1", creds, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("Authorization =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestGCPKMSWrapper(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const keyPath = "/v1/projects/p/locations/global/keyRings/r/cryptoKeys/loom"
+		switch r.URL.Path {
+		case "/token":
+			r.ParseForm()
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(r.Form.Get("assertion"), claims, func(*jwt.Token) (interface{}, error) {
+				return &key.PublicKey, nil
+			}, jwt.WithAudience(srv.URL+"/token"))
+			if err != nil || claims["iss"] != "loom@p.iam.gserviceaccount.com" || claims["scope"] != gcpKMSScope {
+				http.Error(w, "bad assertion", http.StatusUnauthorized)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "ya29", "expires_in": 3600})
+			return
+		case keyPath + ":encrypt", keyPath + ":decrypt":
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer ya29" {
+			http.Error(w, "unauthenticated", http.StatusUnauthorized)
+			return
+		}
+		var in map[string][]byte
+		json.NewDecoder(r.Body).Decode(&in)
+		if strings.HasSuffix(r.URL.Path, ":encrypt") {
+			json.NewEncoder(w).Encode(map[string][]byte{"ciphertext": append([]byte("gcp"), in["plaintext"]...)})
+		} else {
+			json.NewEncoder(w).Encode(map[string][]byte{"plaintext": bytes.TrimPrefix(in["ciphertext"], []byte("gcp"))})
+		}
+	}))
+	defer srv.Close()
+
+	credentials, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "loom@p.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})),
+		"token_uri":    srv.URL + "/token",
+	})
+	path := filepath.Join(t.TempDir(), "sa.json")
+	if err := os.WriteFile(path, credentials, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_OAUTH_ACCESS_TOKEN", "")
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path)
+
+	w, err := NewWrapper(config.KeyStoreConfig{
+		Backend: BackendGCPKMS,
+		KeyID:   "projects/p/locations/global/keyRings/r/cryptoKeys/loom",
+		Address: srv.URL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testWrapperRoundTrip(t, w)
+}
+
+// testWrapperRoundTrip stores a key through w and reads it back from a
+// fresh KeyManager
+func testWrapperRoundTrip(t *testing.T, w KeyWrapper) {
+	t.Helper()
+	storePath := filepath.Join(t.TempDir(), "keys.json")
+	km := NewKeyManager(storePath)
+	km.SetWrapper(w)
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatalf("Unlock: %v", err)
+	}
+	if err := km.StoreKey("k", "K", "", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if env := readStore(t, storePath).KeyEncryption; env == nil || env.Backend != w.Backend() {
+		t.Fatalf("envelope = %+v", env)
+	}
+
+	km = NewKeyManager(storePath)
+	km.SetWrapper(w)
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatalf("Unlock existing store: %v", err)
+	}
+	if got, err := km.GetKey("k"); err != nil || got != "value" {
+		t.Errorf("GetKey = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,211 @@
+package keymanager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+const (
+	gcpKMSEndpoint = "https://cloudkms.googleapis.com"
+	gcpKMSScope    = "https://www.googleapis.com/auth/cloudkms"
+	gcpMetadataURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// gcpKMSWrapper wraps the data key with a Google Cloud KMS key. Access
+// tokens come from GOOGLE_OAUTH_ACCESS_TOKEN, the service account key file
+// named by GOOGLE_APPLICATION_CREDENTIALS, or the metadata server.
+type gcpKMSWrapper struct {
+	keyName  string // projects/P/locations/L/keyRings/R/cryptoKeys/K
+	endpoint string
+	client   *http.Client
+	tokens   *gcpTokenSource
+}
+
+func newGCPKMSWrapper(cfg config.KeyStoreConfig) (*gcpKMSWrapper, error) {
+	if !strings.HasPrefix(cfg.KeyID, "projects/") {
+		return nil, errors.New("gcpkms: security.key_store.key_id must be a key resource name, projects/P/locations/L/keyRings/R/cryptoKeys/K")
+	}
+	client := &http.Client{Timeout: wrapTimeout}
+	tokens, err := newGCPTokenSource(client)
+	if err != nil {
+		return nil, err
+	}
+	return &gcpKMSWrapper{
+		keyName:  cfg.KeyID,
+		endpoint: strings.TrimSuffix(firstNonEmpty(cfg.Address, gcpKMSEndpoint), "/"),
+		client:   client,
+		tokens:   tokens,
+	}, nil
+}
+
+func (g *gcpKMSWrapper) Backend() string { return BackendGCPKMS }
+func (g *gcpKMSWrapper) KeyID() string   { return g.keyName }
+
+func (g *gcpKMSWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var resp struct {
+		Ciphertext []byte `json:"ciphertext"`
+	}
+	if err := g.call(ctx, g.keyName, "encrypt", map[string][]byte{"plaintext": dataKey}, &resp); err != nil {
+		return nil, fmt.Errorf("cloud kms encrypt: %w", err)
+	}
+	return resp.Ciphertext, nil
+}
+
+func (g *gcpKMSWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Plaintext []byte `json:"plaintext"`
+	}
+	if err := g.call(ctx, keyID, "decrypt", map[string][]byte{"ciphertext": wrapped}, &resp); err != nil {
+		return nil, fmt.Errorf("cloud kms decrypt: %w", err)
+	}
+	return resp.Plaintext, nil
+}
+
+func (g *gcpKMSWrapper) call(ctx context.Context, keyName, op string, in, out interface{}) error {
+	token, err := g.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	return postJSON(ctx, g.client, fmt.Sprintf("%s/v1/%s:%s", g.endpoint, keyName, op), header, in, out)
+}
+
+// gcpTokenSource gets and caches OAuth access tokens for Cloud KMS
+type gcpTokenSource struct {
+	client  *http.Client
+	static  string             // GOOGLE_OAUTH_ACCESS_TOKEN
+	account *gcpServiceAccount // From GOOGLE_APPLICATION_CREDENTIALS
+	metaURL string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// gcpServiceAccount is the part of a service account key file used to
+// request tokens
+type gcpServiceAccount struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+func newGCPTokenSource(client *http.Client) (*gcpTokenSource, error) {
+	ts := &gcpTokenSource{client: client, static: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"), metaURL: gcpMetadataURL}
+	if path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS"); path != "" && ts.static == "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("gcpkms: %w", err)
+		}
+		var sa gcpServiceAccount
+		if err := json.Unmarshal(data, &sa); err != nil {
+			return nil, fmt.Errorf("gcpkms: %s: %w", path, err)
+		}
+		if sa.Type != "service_account" || sa.ClientEmail == "" || sa.PrivateKey == "" {
+			return nil, fmt.Errorf("gcpkms: %s is not a service account key file", path)
+		}
+		if sa.TokenURI == "" {
+			sa.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+		ts.account = &sa
+	}
+	return ts, nil
+}
+
+// Token returns an access token, fetching a new one shortly before the
+// cached one expires
+func (ts *gcpTokenSource) Token(ctx context.Context) (string, error) {
+	if ts.static != "" {
+		return ts.static, nil
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if ts.token != "" && time.Until(ts.expires) > time.Minute {
+		return ts.token, nil
+	}
+
+	var req *http.Request
+	var err error
+	if ts.account != nil {
+		req, err = ts.account.tokenRequest(ctx)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, ts.metaURL+"?scopes="+url.QueryEscape(gcpKMSScope), nil)
+		if err == nil {
+			req.Header.Set("Metadata-Flavor", "Google")
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	resp, err := ts.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gcpkms: no access token (set GOOGLE_APPLICATION_CREDENTIALS or run on GCP): %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("gcpkms: token request: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return "", fmt.Errorf("gcpkms: token response: %w", err)
+	}
+	ts.token = tok.AccessToken
+	ts.expires = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return ts.token, nil
+}
+
+// tokenRequest builds the JWT bearer grant that exchanges a signed
+// assertion for an access token
+func (sa *gcpServiceAccount) tokenRequest(ctx context.Context) (*http.Request, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(sa.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("gcpkms: service account key: %w", err)
+	}
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   sa.ClientEmail,
+		"scope": gcpKMSScope,
+		"aud":   sa.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if sa.PrivateKeyID != "" {
+		token.Header["kid"] = sa.PrivateKeyID
+	}
+	assertion, err := token.SignedString(key)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sa.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
@@ -1,6 +1,7 @@
 package keymanager
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
@@ -28,11 +29,15 @@ type KeyEntry struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
-// KeyStore represents the encrypted key storage
+// KeyStore represents the encrypted key storage. Since version 2.0 keys
+// are encrypted with a random data key, itself wrapped by a KeyWrapper;
+// version 1.0 stores encrypted each key with the password and are upgraded
+// when unlocked.
 type KeyStore struct {
 	Version        string               `json:"version"`         // Schema version
 	PasswordSalt   string               `json:"password_salt"`   // Unencrypted salt for password validation
 	PasswordVerify string               `json:"password_verify"` // Hash to verify password correctness
+	KeyEncryption  *Envelope            `json:"key_encryption,omitempty"`
 	Keys           map[string]*KeyEntry `json:"keys"`
 }
 
@@ -43,12 +48,15 @@ type KeyManager struct {
 	store     *KeyStore
 	mu        sync.RWMutex
 	unlocked  bool
+	wrapper   KeyWrapper // nil wraps the data key with the password
+	dataKey   []byte     // Set while unlocked
 }
 
 const (
-	saltSize   = 32
-	keySize    = 32
-	iterations = 100000
+	saltSize     = 32
+	keySize      = 32
+	iterations   = 100000
+	storeVersion = "2.0"
 )
 
 // NewKeyManager creates a new key manager instance
@@ -71,17 +79,23 @@ func (km *KeyManager) Unlock(password string) error {
 	km.password = []byte(password)
 
 	// Try to load existing store
+	created := false
 	if err := km.loadStore(); err != nil {
 		// If store doesn't exist, initialize a new one
 		if os.IsNotExist(err) {
 			km.store = &KeyStore{
-				Version: "1.0",
+				Version: storeVersion,
 				Keys:    make(map[string]*KeyEntry),
 			}
 			// Generate and store password salt and verification hash
 			if err := km.initializePasswordSalt(); err != nil {
 				return fmt.Errorf("failed to initialize password: %w", err)
 			}
+			if err := km.newDataKey(); err != nil {
+				km.password = nil
+				return fmt.Errorf("failed to initialize key store: %w", err)
+			}
+			created = true
 			// Save the empty store
 			if err := km.saveStore(); err != nil {
 				return fmt.Errorf("failed to initialize key store: %w", err)
@@ -99,6 +113,14 @@ func (km *KeyManager) Unlock(password string) error {
 		}
 	}
 
+	if !created {
+		if err := km.openDataKey(); err != nil {
+			km.password = nil
+			km.dataKey = nil
+			return err
+		}
+	}
+
 	km.unlocked = true
 	return nil
 }
@@ -161,7 +183,7 @@ func (km *KeyManager) StoreKey(id, name, description, key string) error {
 	}
 
 	// Encrypt the key
-	encryptedData, err := km.encrypt([]byte(key))
+	encryptedData, err := seal(km.dataKey, []byte(key))
 	if err != nil {
 		return fmt.Errorf("failed to encrypt key: %w", err)
 	}
@@ -204,7 +226,7 @@ func (km *KeyManager) GetKey(id string) (string, error) {
 		return "", fmt.Errorf("failed to decode key: %w", err)
 	}
 
-	decryptedData, err := km.decrypt(encryptedData)
+	decryptedData, err := unseal(km.dataKey, encryptedData)
 	if err != nil {
 		return "", fmt.Errorf("failed to decrypt key: %w", err)
 	}
@@ -255,7 +277,8 @@ func (km *KeyManager) ListKeys() ([]*KeyEntry, error) {
 	return keys, nil
 }
 
-// ChangePassword changes the master password and re-encrypts all stored keys
+// ChangePassword changes the master password. Keys stay encrypted with the
+// same data key; when the password wraps it, it is rewrapped.
 func (km *KeyManager) ChangePassword(oldPassword, newPassword string) error {
 	km.mu.Lock()
 	defer km.mu.Unlock()
@@ -269,20 +292,6 @@ func (km *KeyManager) ChangePassword(oldPassword, newPassword string) error {
 		return fmt.Errorf("old password is incorrect: %w", err)
 	}
 
-	// Store all decrypted keys temporarily using current password
-	decryptedKeys := make(map[string]string)
-	for id, entry := range km.store.Keys {
-		decryptedData, err := km.decrypt([]byte(entry.EncryptedData))
-		if err != nil {
-			return fmt.Errorf("failed to decrypt key %s: %w", id, err)
-		}
-		decrypted, err := base64.StdEncoding.DecodeString(string(decryptedData))
-		if err != nil {
-			return fmt.Errorf("failed to decode key %s: %w", id, err)
-		}
-		decryptedKeys[id] = string(decrypted)
-	}
-
 	// Change the password
 	km.password = []byte(newPassword)
 
@@ -291,16 +300,10 @@ func (km *KeyManager) ChangePassword(oldPassword, newPassword string) error {
 		return fmt.Errorf("failed to initialize new password: %w", err)
 	}
 
-	// Re-encrypt all keys with new password
-	for id, plaintext := range decryptedKeys {
-		encryptedData, err := km.encrypt([]byte(plaintext))
-		if err != nil {
-			return fmt.Errorf("failed to re-encrypt key %s: %w", id, err)
+	if km.store.KeyEncryption.Backend == BackendPassword {
+		if err := km.wrapDataKey(context.Background(), km.keyWrapper()); err != nil {
+			return fmt.Errorf("failed to rewrap data key: %w", err)
 		}
-
-		entry := km.store.Keys[id]
-		entry.EncryptedData = base64.StdEncoding.EncodeToString(encryptedData)
-		entry.UpdatedAt = time.Now()
 	}
 
 	// Persist to disk
@@ -323,12 +326,68 @@ func (km *KeyManager) Lock() {
 		}
 		km.password = nil
 	}
+	for i := range km.dataKey {
+		km.dataKey[i] = 0
+	}
+	km.dataKey = nil
 
 	km.unlocked = false
 }
 
-// encrypt encrypts data using AES-GCM
-func (km *KeyManager) encrypt(plaintext []byte) ([]byte, error) {
+// Export returns the key store file as stored on disk, keys still
+// encrypted, or nil if nothing has been saved yet
+func (km *KeyManager) Export() ([]byte, error) {
+	km.mu.RLock()
+	defer km.mu.RUnlock()
+
+	data, err := os.ReadFile(km.storePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// Import replaces the key store file with data from Export. An unlocked
+// manager reloads it and unwraps its data key, which needs the password or
+// KMS key that protected it when it was exported.
+func (km *KeyManager) Import(data []byte) error {
+	km.mu.Lock()
+	defer km.mu.Unlock()
+
+	var store KeyStore
+	if err := json.Unmarshal(data, &store); err != nil {
+		return fmt.Errorf("invalid key store: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(km.storePath), 0700); err != nil {
+		return err
+	}
+	tmp := km.storePath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, km.storePath); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if store.Keys == nil {
+		store.Keys = make(map[string]*KeyEntry)
+	}
+	km.store = &store
+	if !km.unlocked {
+		return nil
+	}
+	if err := km.openDataKey(); err != nil {
+		km.unlocked = false
+		return fmt.Errorf("imported key store cannot be unlocked: %w", err)
+	}
+	return nil
+}
+
+// passwordEncrypt encrypts data using AES-GCM with a key derived from the
+// password and a random salt
+func passwordEncrypt(password, plaintext []byte) ([]byte, error) {
 	// Generate salt
 	salt := make([]byte, saltSize)
 	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
@@ -336,7 +395,7 @@ func (km *KeyManager) encrypt(plaintext []byte) ([]byte, error) {
 	}
 
 	// Derive key from password
-	key := pbkdf2.Key(km.password, salt, iterations, keySize, sha256.New)
+	key := pbkdf2.Key(password, salt, iterations, keySize, sha256.New)
 
 	// Create cipher
 	block, err := aes.NewCipher(key)
@@ -367,8 +426,8 @@ func (km *KeyManager) encrypt(plaintext []byte) ([]byte, error) {
 	return result, nil
 }
 
-// decrypt decrypts data using AES-GCM
-func (km *KeyManager) decrypt(data []byte) ([]byte, error) {
+// passwordDecrypt decrypts data from passwordEncrypt
+func passwordDecrypt(password, data []byte) ([]byte, error) {
 	if len(data) < saltSize {
 		return nil, errors.New("invalid encrypted data")
 	}
@@ -378,7 +437,7 @@ func (km *KeyManager) decrypt(data []byte) ([]byte, error) {
 	data = data[saltSize:]
 
 	// Derive key from password
-	key := pbkdf2.Key(km.password, salt, iterations, keySize, sha256.New)
+	key := pbkdf2.Key(password, salt, iterations, keySize, sha256.New)
 
 	// Create cipher
 	block, err := aes.NewCipher(key)
@@ -262,3 +262,36 @@ func TestKeyManager_LockedOperations(t *testing.T) {
 		t.Error("ListKeys on locked store should fail")
 	}
 }
+
+func TestKeyManager_ExportImport(t *testing.T) {
+	tmpDir := t.TempDir()
+	km := NewKeyManager(filepath.Join(tmpDir, "keys.json"))
+
+	if data, err := km.Export(); err != nil || data != nil {
+		t.Fatalf("Export of unsaved store = %q, %v", data, err)
+	}
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := km.StoreKey("key1", "name", "desc", "before"); err != nil {
+		t.Fatal(err)
+	}
+	exported, err := km.Export()
+	if err != nil || len(exported) == 0 {
+		t.Fatalf("Export = %d bytes, %v", len(exported), err)
+	}
+
+	if err := km.StoreKey("key1", "name", "desc", "after"); err != nil {
+		t.Fatal(err)
+	}
+	if err := km.Import(exported); err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if got, err := km.GetKey("key1"); err != nil || got != "before" {
+		t.Errorf("GetKey after import = %q, %v", got, err)
+	}
+
+	if err := km.Import([]byte("not json")); err == nil {
+		t.Error("Import should reject invalid data")
+	}
+}
@@ -0,0 +1,99 @@
+package keymanager
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// vaultWrapper wraps the data key with a key of HashiCorp Vault's transit
+// secrets engine. The token is read from VAULT_TOKEN.
+type vaultWrapper struct {
+	addr      string
+	mount     string
+	key       string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+func newVaultWrapper(cfg config.KeyStoreConfig) (*vaultWrapper, error) {
+	v := &vaultWrapper{
+		addr:      strings.TrimSuffix(firstNonEmpty(cfg.Address, os.Getenv("VAULT_ADDR")), "/"),
+		mount:     strings.Trim(firstNonEmpty(cfg.Mount, "transit"), "/"),
+		key:       cfg.KeyID,
+		token:     os.Getenv("VAULT_TOKEN"),
+		namespace: os.Getenv("VAULT_NAMESPACE"),
+		client:    &http.Client{Timeout: wrapTimeout},
+	}
+	switch {
+	case v.addr == "":
+		return nil, errors.New("vault: set security.key_store.address or VAULT_ADDR")
+	case v.token == "":
+		return nil, errors.New("vault: VAULT_TOKEN is not set")
+	case v.key == "":
+		return nil, errors.New("vault: security.key_store.key_id names no transit key")
+	}
+	return v, nil
+}
+
+func (v *vaultWrapper) Backend() string { return BackendVault }
+func (v *vaultWrapper) KeyID() string   { return v.key }
+
+func (v *vaultWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Ciphertext string `json:"ciphertext"`
+		} `json:"data"`
+	}
+	in := map[string]string{"plaintext": base64.StdEncoding.EncodeToString(dataKey)}
+	if err := postJSON(ctx, v.client, v.url("encrypt", v.key), v.header(), in, &resp); err != nil {
+		return nil, fmt.Errorf("vault encrypt: %w", err)
+	}
+	if resp.Data.Ciphertext == "" {
+		return nil, errors.New("vault encrypt: no ciphertext returned")
+	}
+	return []byte(resp.Data.Ciphertext), nil
+}
+
+func (v *vaultWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	var resp struct {
+		Data struct {
+			Plaintext string `json:"plaintext"`
+		} `json:"data"`
+	}
+	in := map[string]string{"ciphertext": string(wrapped)}
+	if err := postJSON(ctx, v.client, v.url("decrypt", keyID), v.header(), in, &resp); err != nil {
+		return nil, fmt.Errorf("vault decrypt: %w", err)
+	}
+	return base64.StdEncoding.DecodeString(resp.Data.Plaintext)
+}
+
+func (v *vaultWrapper) url(op, key string) string {
+	return fmt.Sprintf("%s/v1/%s/%s/%s", v.addr, v.mount, op, url.PathEscape(key))
+}
+
+func (v *vaultWrapper) header() http.Header {
+	h := http.Header{}
+	h.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		h.Set("X-Vault-Namespace", v.namespace)
+	}
+	return h
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
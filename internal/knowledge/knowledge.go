@@ -0,0 +1,507 @@
+// Package knowledge keeps a graph linking lessons to the beads, agents,
+// commits and files they came from. Commits are read from each project's
+// git history through their Bead and Agent trailers, together with the
+// files they changed; lessons link to the bead and agent that produced them
+// and to the files they mention. Traversals answer questions such as which
+// lessons touch pkg/provider, so the lessons put in an agent's prompt can
+// be scoped to the files it is about to edit.
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Node kinds
+const (
+	KindLesson = "lesson"
+	KindBead   = "bead"
+	KindAgent  = "agent"
+	KindCommit = "commit"
+	KindFile   = "file"
+)
+
+// Node is a lesson, bead, agent, commit or file. Files are identified by
+// their path in the project's repository.
+type Node struct {
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+}
+
+// Edge links a lesson or commit to a bead, agent or file it came from or
+// touched. Edges always point from the lesson or commit.
+type Edge struct {
+	ProjectID string    `json:"project_id"`
+	From      Node      `json:"from"`
+	To        Node      `json:"to"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists the graph's edges
+type Store interface {
+	// SaveKnowledgeEdges stores edges, ignoring ones already stored
+	SaveKnowledgeEdges(edges []*Edge) error
+	// ListKnowledgeEdges returns a project's edges with n at either end
+	ListKnowledgeEdges(projectID string, n Node) ([]*Edge, error)
+	// ListKnowledgeNodes returns a project's nodes of a kind whose IDs
+	// start with prefix
+	ListKnowledgeNodes(projectID, kind, prefix string) ([]Node, error)
+}
+
+// Sources are where indexing reads a project's commits and lessons from.
+// Mentions finds the file paths a lesson's text mentions.
+type Sources struct {
+	Projects func() []string
+	Commits  func(ctx context.Context, projectID string) ([]git.CommitMetadata, error)
+	Lessons  func(projectID string) ([]*models.Lesson, error)
+	Mentions func(text string) []string
+}
+
+// Config configures the graph
+type Config struct {
+	Interval time.Duration // Time between indexing runs
+}
+
+// DefaultConfig indexes every 15 minutes
+func DefaultConfig() Config {
+	return Config{Interval: 15 * time.Minute}
+}
+
+// IndexReport is what an indexing run linked
+type IndexReport struct {
+	Projects  int       `json:"projects"`
+	Commits   int       `json:"commits"`
+	Lessons   int       `json:"lessons"`
+	Errors    []string  `json:"errors,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// LessonHit is a lesson found by LessonsTouching with the files that led
+// to it. Direct lessons mention one of the files themselves; the others
+// came from a bead whose commits changed them.
+type LessonHit struct {
+	LessonID string   `json:"lesson_id"`
+	Files    []string `json:"files"`
+	Beads    []string `json:"beads,omitempty"`
+	Direct   bool     `json:"direct"`
+}
+
+// Subgraph is the part of the graph around a node
+type Subgraph struct {
+	Nodes []Node  `json:"nodes"`
+	Edges []*Edge `json:"edges"`
+}
+
+// Traversal bounds, so a broad path or a busy node stays cheap
+const (
+	maxFilesPerPath = 500
+	maxWalkDepth    = 3
+	maxWalkNodes    = 500
+)
+
+// Graph links lessons to where they came from
+type Graph struct {
+	store Store
+	cfg   Config
+
+	mu   sync.Mutex
+	src  Sources
+	last *IndexReport
+	stop chan struct{}
+}
+
+// New returns a graph over store, or nil without one
+func New(store Store, cfg Config) *Graph {
+	if store == nil {
+		return nil
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultConfig().Interval
+	}
+	return &Graph{store: store, cfg: cfg}
+}
+
+// SetSources sets where indexing reads from
+func (g *Graph) SetSources(src Sources) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.src = src
+}
+
+func (g *Graph) sources() Sources {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.src
+}
+
+// RecordLesson links a lesson to the bead and agent it came from and to
+// the files it mentions
+func (g *Graph) RecordLesson(l *models.Lesson, files []string) error {
+	if g == nil || l == nil || l.ID == "" {
+		return nil
+	}
+	from := Node{Kind: KindLesson, ID: l.ID}
+	var edges []*Edge
+	link := func(kind, id string) {
+		if id != "" {
+			edges = append(edges, &Edge{ProjectID: l.ProjectID, From: from, To: Node{Kind: kind, ID: id}, CreatedAt: l.CreatedAt})
+		}
+	}
+	link(KindBead, l.SourceBeadID)
+	link(KindAgent, l.SourceAgentID)
+	for _, f := range files {
+		link(KindFile, f)
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	return g.store.SaveKnowledgeEdges(edges)
+}
+
+// RecordCommit links a commit to the bead and agent of its trailers and
+// to the files it changed
+func (g *Graph) RecordCommit(projectID string, c git.CommitMetadata) error {
+	if g == nil || c.SHA == "" {
+		return nil
+	}
+	from := Node{Kind: KindCommit, ID: c.SHA}
+	var edges []*Edge
+	link := func(kind, id string) {
+		if id != "" {
+			edges = append(edges, &Edge{ProjectID: projectID, From: from, To: Node{Kind: kind, ID: id}, CreatedAt: c.Timestamp})
+		}
+	}
+	link(KindBead, c.BeadID)
+	link(KindAgent, c.AgentID)
+	for _, f := range c.Files {
+		link(KindFile, f)
+	}
+	if len(edges) == 0 {
+		return nil
+	}
+	return g.store.SaveKnowledgeEdges(edges)
+}
+
+// Index links the recent commits and lessons of every project
+func (g *Graph) Index(ctx context.Context) (*IndexReport, error) {
+	if g == nil {
+		return nil, fmt.Errorf("knowledge graph not available")
+	}
+	src := g.sources()
+	report := &IndexReport{StartedAt: time.Now()}
+	if src.Projects != nil {
+		for _, projectID := range src.Projects() {
+			if err := ctx.Err(); err != nil {
+				return report, err
+			}
+			g.indexProject(ctx, src, projectID, report)
+		}
+	}
+	g.mu.Lock()
+	g.last = report
+	g.mu.Unlock()
+	return report, nil
+}
+
+// IndexProject links the recent commits and lessons of one project
+func (g *Graph) IndexProject(ctx context.Context, projectID string) (*IndexReport, error) {
+	if g == nil {
+		return nil, fmt.Errorf("knowledge graph not available")
+	}
+	report := &IndexReport{StartedAt: time.Now()}
+	g.indexProject(ctx, g.sources(), projectID, report)
+	return report, nil
+}
+
+func (g *Graph) indexProject(ctx context.Context, src Sources, projectID string, report *IndexReport) {
+	report.Projects++
+	fail := func(what string, err error) {
+		report.Errors = append(report.Errors, fmt.Sprintf("%s: %s: %v", projectID, what, err))
+	}
+
+	if src.Commits != nil {
+		commits, err := src.Commits(ctx, projectID)
+		if err != nil {
+			fail("commits", err)
+		}
+		for _, c := range commits {
+			if err := g.RecordCommit(projectID, c); err != nil {
+				fail("commit "+c.SHA, err)
+				break
+			}
+			report.Commits++
+		}
+	}
+
+	if src.Lessons != nil {
+		lessons, err := src.Lessons(projectID)
+		if err != nil {
+			fail("lessons", err)
+		}
+		for _, l := range lessons {
+			var files []string
+			if src.Mentions != nil {
+				files = src.Mentions(l.Title + " " + l.Detail)
+			}
+			if err := g.RecordLesson(l, files); err != nil {
+				fail("lesson "+l.ID, err)
+				break
+			}
+			report.Lessons++
+		}
+	}
+}
+
+// LastIndex returns the report of the latest scheduled run, or nil
+func (g *Graph) LastIndex() *IndexReport {
+	if g == nil {
+		return nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.last
+}
+
+// Neighbors returns the nodes linked to n, of the given kind or of any
+// kind when kind is empty
+func (g *Graph) Neighbors(projectID string, n Node, kind string) ([]Node, error) {
+	if g == nil {
+		return nil, fmt.Errorf("knowledge graph not available")
+	}
+	edges, err := g.store.ListKnowledgeEdges(projectID, n)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[Node]bool)
+	var nodes []Node
+	for _, e := range edges {
+		other := e.To
+		if e.To == n {
+			other = e.From
+		}
+		if (kind == "" || other.Kind == kind) && !seen[other] {
+			seen[other] = true
+			nodes = append(nodes, other)
+		}
+	}
+	return nodes, nil
+}
+
+// Walk returns the nodes and edges within depth links of start
+func (g *Graph) Walk(projectID string, start Node, depth int) (*Subgraph, error) {
+	if g == nil {
+		return nil, fmt.Errorf("knowledge graph not available")
+	}
+	if depth <= 0 {
+		depth = 1
+	}
+	if depth > maxWalkDepth {
+		depth = maxWalkDepth
+	}
+
+	sub := &Subgraph{Nodes: []Node{start}, Edges: []*Edge{}}
+	visited := map[Node]bool{start: true}
+	seenEdges := make(map[Edge]bool)
+	frontier := []Node{start}
+	for d := 0; d < depth && len(frontier) > 0; d++ {
+		var next []Node
+		for _, n := range frontier {
+			edges, err := g.store.ListKnowledgeEdges(projectID, n)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range edges {
+				key := Edge{ProjectID: e.ProjectID, From: e.From, To: e.To}
+				if !seenEdges[key] {
+					seenEdges[key] = true
+					sub.Edges = append(sub.Edges, e)
+				}
+				for _, other := range []Node{e.From, e.To} {
+					if !visited[other] && len(sub.Nodes) < maxWalkNodes {
+						visited[other] = true
+						sub.Nodes = append(sub.Nodes, other)
+						next = append(next, other)
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+	return sub, nil
+}
+
+// LessonsTouching returns the lessons linked to the files at or under the
+// given paths, either because they mention a file or because a commit of
+// the bead they came from changed one. Lessons that mention a file come
+// first, then those reached through more files.
+func (g *Graph) LessonsTouching(projectID string, paths ...string) ([]LessonHit, error) {
+	if g == nil {
+		return nil, nil
+	}
+	hits := make(map[string]*LessonHit)
+	hit := func(lessonID, file string) *LessonHit {
+		h, ok := hits[lessonID]
+		if !ok {
+			h = &LessonHit{LessonID: lessonID}
+			hits[lessonID] = h
+		}
+		if !contains(h.Files, file) {
+			h.Files = append(h.Files, file)
+		}
+		return h
+	}
+	beadsOf := make(map[string][]Node)
+	lessonsOf := make(map[string][]Node)
+
+	for _, file := range g.files(projectID, paths) {
+		edges, err := g.store.ListKnowledgeEdges(projectID, Node{Kind: KindFile, ID: file})
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range edges {
+			switch e.From.Kind {
+			case KindLesson:
+				hit(e.From.ID, file).Direct = true
+
+			case KindCommit:
+				beads, ok := beadsOf[e.From.ID]
+				if !ok {
+					if beads, err = g.Neighbors(projectID, e.From, KindBead); err != nil {
+						return nil, err
+					}
+					beadsOf[e.From.ID] = beads
+				}
+				for _, b := range beads {
+					lessons, ok := lessonsOf[b.ID]
+					if !ok {
+						if lessons, err = g.Neighbors(projectID, b, KindLesson); err != nil {
+							return nil, err
+						}
+						lessonsOf[b.ID] = lessons
+					}
+					for _, l := range lessons {
+						h := hit(l.ID, file)
+						if !contains(h.Beads, b.ID) {
+							h.Beads = append(h.Beads, b.ID)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	list := make([]LessonHit, 0, len(hits))
+	for _, h := range hits {
+		sort.Strings(h.Files)
+		sort.Strings(h.Beads)
+		list = append(list, *h)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Direct != list[j].Direct {
+			return list[i].Direct
+		}
+		if len(list[i].Files) != len(list[j].Files) {
+			return len(list[i].Files) > len(list[j].Files)
+		}
+		return list[i].LessonID < list[j].LessonID
+	})
+	return list, nil
+}
+
+// files returns the file nodes at or under each path
+func (g *Graph) files(projectID string, paths []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, p := range paths {
+		p = strings.Trim(strings.TrimPrefix(p, "./"), "/")
+		if p == "" {
+			continue
+		}
+		nodes, err := g.store.ListKnowledgeNodes(projectID, KindFile, p)
+		if err != nil {
+			log.Printf("[Knowledge] Failed to list files under %s: %v", p, err)
+			continue
+		}
+		n := 0
+		for _, node := range nodes {
+			// A prefix match on "pkg/prov" must not take in pkg/provider
+			if node.ID != p && !strings.HasPrefix(node.ID, p+"/") {
+				continue
+			}
+			if !seen[node.ID] && n < maxFilesPerPath {
+				seen[node.ID] = true
+				files = append(files, node.ID)
+				n++
+			}
+		}
+	}
+	return files
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Start indexes now and then on the configured interval
+func (g *Graph) Start(ctx context.Context) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	if g.stop != nil {
+		g.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	g.stop = stop
+	g.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(g.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if report, err := g.Index(ctx); err != nil {
+				log.Printf("[Knowledge] Indexing failed: %v", err)
+			} else if len(report.Errors) > 0 {
+				log.Printf("[Knowledge] Indexed %d commits and %d lessons with %d errors, first: %s",
+					report.Commits, report.Lessons, len(report.Errors), report.Errors[0])
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops scheduled indexing
+func (g *Graph) Close() {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stop != nil {
+		close(g.stop)
+		g.stop = nil
+	}
+}
@@ -0,0 +1,200 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// memStore is a Store kept in a slice
+type memStore struct {
+	edges []*Edge
+}
+
+func (m *memStore) SaveKnowledgeEdges(edges []*Edge) error {
+	for _, e := range edges {
+		dup := false
+		for _, have := range m.edges {
+			if have.ProjectID == e.ProjectID && have.From == e.From && have.To == e.To {
+				dup = true
+			}
+		}
+		if !dup {
+			m.edges = append(m.edges, e)
+		}
+	}
+	return nil
+}
+
+func (m *memStore) ListKnowledgeEdges(projectID string, n Node) ([]*Edge, error) {
+	var list []*Edge
+	for _, e := range m.edges {
+		if e.ProjectID == projectID && (e.From == n || e.To == n) {
+			list = append(list, e)
+		}
+	}
+	return list, nil
+}
+
+func (m *memStore) ListKnowledgeNodes(projectID, kind, prefix string) ([]Node, error) {
+	seen := make(map[Node]bool)
+	var nodes []Node
+	for _, e := range m.edges {
+		for _, n := range []Node{e.From, e.To} {
+			if e.ProjectID == projectID && n.Kind == kind && strings.HasPrefix(n.ID, prefix) && !seen[n] {
+				seen[n] = true
+				nodes = append(nodes, n)
+			}
+		}
+	}
+	return nodes, nil
+}
+
+// newTestGraph links two beads' commits and lessons:
+//
+//	bd-1: commit c1 changed pkg/provider/client.go; lesson l1
+//	bd-2: commit c2 changed pkg/providers.go and cmd/main.go; lesson l2
+//	lesson l3 mentions pkg/provider/retry.go and has no bead
+func newTestGraph(t *testing.T) (*Graph, *memStore) {
+	t.Helper()
+	store := &memStore{}
+	g := New(store, Config{})
+	g.SetSources(Sources{
+		Projects: func() []string { return []string{"p1"} },
+		Commits: func(_ context.Context, projectID string) ([]git.CommitMetadata, error) {
+			return []git.CommitMetadata{
+				{SHA: "c1", BeadID: "bd-1", AgentID: "agent-a", Files: []string{"pkg/provider/client.go"}},
+				{SHA: "c2", BeadID: "bd-2", AgentID: "agent-b", Files: []string{"pkg/providers.go", "cmd/main.go"}},
+			}, nil
+		},
+		Lessons: func(projectID string) ([]*models.Lesson, error) {
+			return []*models.Lesson{
+				{ID: "l1", ProjectID: projectID, Title: "Close bodies", SourceBeadID: "bd-1", SourceAgentID: "agent-a"},
+				{ID: "l2", ProjectID: projectID, Title: "Register providers", SourceBeadID: "bd-2"},
+				{ID: "l3", ProjectID: projectID, Title: "Back off", Detail: "in pkg/provider/retry.go"},
+			}, nil
+		},
+		Mentions: func(text string) []string {
+			if strings.Contains(text, "pkg/provider/retry.go") {
+				return []string{"pkg/provider/retry.go"}
+			}
+			return nil
+		},
+	})
+	report, err := g.Index(context.Background())
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if report.Projects != 1 || report.Commits != 2 || report.Lessons != 3 || len(report.Errors) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	return g, store
+}
+
+func TestLessonsTouching(t *testing.T) {
+	g, _ := newTestGraph(t)
+
+	hits, err := g.LessonsTouching("p1", "pkg/provider")
+	if err != nil {
+		t.Fatalf("LessonsTouching failed: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("expected 2 lessons under pkg/provider, got %+v", hits)
+	}
+	// The lesson that mentions a file comes first
+	if hits[0].LessonID != "l3" || !hits[0].Direct || hits[0].Files[0] != "pkg/provider/retry.go" {
+		t.Errorf("unexpected first hit %+v", hits[0])
+	}
+	if hits[1].LessonID != "l1" || hits[1].Direct || hits[1].Beads[0] != "bd-1" {
+		t.Errorf("unexpected second hit %+v", hits[1])
+	}
+
+	// pkg/providers.go is not under pkg/provider
+	for _, h := range hits {
+		if h.LessonID == "l2" {
+			t.Error("expected a path prefix to match whole directories only")
+		}
+	}
+
+	if hits, _ := g.LessonsTouching("p1", "cmd/main.go"); len(hits) != 1 || hits[0].LessonID != "l2" {
+		t.Errorf("expected l2 for cmd/main.go, got %+v", hits)
+	}
+	if hits, _ := g.LessonsTouching("p2", "pkg/provider"); len(hits) != 0 {
+		t.Errorf("expected nothing from another project, got %+v", hits)
+	}
+}
+
+func TestIndexIsIdempotent(t *testing.T) {
+	g, store := newTestGraph(t)
+	before := len(store.edges)
+	if _, err := g.Index(context.Background()); err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if len(store.edges) != before {
+		t.Errorf("expected a second run to add no edges, had %d, now %d", before, len(store.edges))
+	}
+}
+
+func TestIndexReportsSourceErrors(t *testing.T) {
+	g := New(&memStore{}, Config{})
+	g.SetSources(Sources{
+		Projects: func() []string { return []string{"p1"} },
+		Commits: func(context.Context, string) ([]git.CommitMetadata, error) {
+			return nil, errors.New("not a git repository")
+		},
+	})
+	report, err := g.Index(context.Background())
+	if err != nil {
+		t.Fatalf("Index failed: %v", err)
+	}
+	if len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "not a git repository") {
+		t.Errorf("expected the commit error reported, got %+v", report)
+	}
+}
+
+func TestWalkAndNeighbors(t *testing.T) {
+	g, _ := newTestGraph(t)
+
+	agents, err := g.Neighbors("p1", Node{Kind: KindCommit, ID: "c1"}, KindAgent)
+	if err != nil || len(agents) != 1 || agents[0].ID != "agent-a" {
+		t.Errorf("expected c1's agent, got %v, %v", agents, err)
+	}
+
+	sub, err := g.Walk("p1", Node{Kind: KindFile, ID: "pkg/provider/client.go"}, 2)
+	if err != nil {
+		t.Fatalf("Walk failed: %v", err)
+	}
+	found := make(map[Node]bool)
+	for _, n := range sub.Nodes {
+		found[n] = true
+	}
+	// file <- c1 -> bd-1 <- l1, two links out
+	for _, n := range []Node{{KindCommit, "c1"}, {KindBead, "bd-1"}, {KindAgent, "agent-a"}} {
+		if !found[n] {
+			t.Errorf("expected %v within 2 links, got %v", n, sub.Nodes)
+		}
+	}
+	if found[Node{KindLesson, "l1"}] {
+		t.Error("expected l1 to be 3 links away")
+	}
+}
+
+func TestRecordLessonUsesCreatedAt(t *testing.T) {
+	store := &memStore{}
+	g := New(store, Config{})
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := g.RecordLesson(&models.Lesson{ID: "l1", ProjectID: "p1", SourceBeadID: "bd-1", CreatedAt: at}, []string{"a.go"}); err != nil {
+		t.Fatalf("RecordLesson failed: %v", err)
+	}
+	if len(store.edges) != 2 || !store.edges[0].CreatedAt.Equal(at) {
+		t.Errorf("unexpected edges %+v", store.edges)
+	}
+	if New(nil, Config{}) != nil {
+		t.Error("expected no graph without a store")
+	}
+}
@@ -6,7 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"strings"
+	"sort"
 	"sync"
 	"time"
 )
@@ -242,44 +242,146 @@ func (m *Manager) Query(limit int, levelFilter, sourceFilter, agentID, beadID, p
 		return m.GetRecent(limit, levelFilter, sourceFilter, agentID, beadID, projectID, since, until), nil
 	}
 
-	query := `SELECT id, timestamp, level, source, message, metadata_json FROM logs WHERE 1=1`
+	where, args := Filter{
+		Level: levelFilter, Source: sourceFilter, AgentID: agentID, BeadID: beadID, ProjectID: projectID,
+		Since: since, Until: until,
+	}.where()
+	query := `SELECT id, timestamp, level, source, message, metadata_json FROM logs WHERE 1=1` + where
+	query += " ORDER BY timestamp DESC"
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	return m.queryLogs(query, args...)
+}
+
+// Filter selects log entries; zero fields match everything
+type Filter struct {
+	Level     string
+	Source    string
+	AgentID   string
+	BeadID    string
+	ProjectID string
+	Since     time.Time
+	Until     time.Time
+}
+
+// where builds the SQL conditions of f, to follow "WHERE 1=1"
+func (f Filter) where() (string, []interface{}) {
+	query := ""
 	args := make([]interface{}, 0)
 
-	if !since.IsZero() {
+	if !f.Since.IsZero() {
 		query += " AND timestamp >= ?"
-		args = append(args, since)
+		args = append(args, f.Since)
 	}
-	if !until.IsZero() {
+	if !f.Until.IsZero() {
 		query += " AND timestamp <= ?"
-		args = append(args, until)
+		args = append(args, f.Until)
 	}
-	if levelFilter != "" {
+	if f.Level != "" {
 		query += " AND level = ?"
-		args = append(args, levelFilter)
+		args = append(args, f.Level)
 	}
-	if sourceFilter != "" {
+	if f.Source != "" {
 		query += " AND source = ?"
-		args = append(args, sourceFilter)
+		args = append(args, f.Source)
 	}
-	if agentID != "" {
+	if f.AgentID != "" {
 		query += " AND agent_id = ?"
-		args = append(args, agentID)
+		args = append(args, f.AgentID)
 	}
-	if beadID != "" {
+	if f.BeadID != "" {
 		query += " AND bead_id = ?"
-		args = append(args, beadID)
+		args = append(args, f.BeadID)
 	}
-	if projectID != "" {
+	if f.ProjectID != "" {
 		query += " AND project_id = ?"
-		args = append(args, projectID)
+		args = append(args, f.ProjectID)
 	}
 
-	query += " ORDER BY timestamp DESC"
+	return query, args
+}
+
+// PageKey is the position of a log entry in a page of entries, which are
+// ordered by timestamp with ties broken by ID
+type PageKey struct {
+	Timestamp time.Time
+	ID        string
+}
+
+// before reports whether k sorts before other in ascending order
+func (k PageKey) before(other PageKey) bool {
+	if !k.Timestamp.Equal(other.Timestamp) {
+		return k.Timestamp.Before(other.Timestamp)
+	}
+	return k.ID < other.ID
+}
+
+// QueryPage returns one page of the log entries matching f, newest first
+// unless ascending, and how many entries match in all. The page continues
+// after the entry at after when it is set, and otherwise skips offset
+// entries.
+func (m *Manager) QueryPage(f Filter, after *PageKey, limit, offset int, ascending bool) ([]LogEntry, int, error) {
+	if m.db == nil {
+		logs := m.GetRecent(MaxBufferSize, f.Level, f.Source, f.AgentID, f.BeadID, f.ProjectID, f.Since, f.Until)
+		sortsBefore := func(a, b PageKey) bool {
+			if ascending {
+				return a.before(b)
+			}
+			return b.before(a)
+		}
+		sort.SliceStable(logs, func(i, j int) bool {
+			return sortsBefore(PageKey{logs[i].Timestamp, logs[i].ID}, PageKey{logs[j].Timestamp, logs[j].ID})
+		})
+		total := len(logs)
+		if after != nil {
+			start := sort.Search(len(logs), func(i int) bool {
+				return sortsBefore(*after, PageKey{logs[i].Timestamp, logs[i].ID})
+			})
+			logs = logs[start:]
+		} else {
+			if offset > total {
+				offset = total
+			}
+			logs = logs[offset:]
+		}
+		if limit > 0 && limit < len(logs) {
+			logs = logs[:limit]
+		}
+		return logs, total, nil
+	}
+
+	where, args := f.where()
+	var total int
+	if err := m.db.QueryRow("SELECT COUNT(*) FROM logs WHERE 1=1"+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count logs: %w", err)
+	}
+
+	dir, op := "DESC", "<"
+	if ascending {
+		dir, op = "ASC", ">"
+	}
+	query := `SELECT id, timestamp, level, source, message, metadata_json FROM logs WHERE 1=1` + where
+	if after != nil {
+		query += " AND (timestamp " + op + " ? OR (timestamp = ? AND id " + op + " ?))"
+		args = append(args, after.Timestamp, after.Timestamp, after.ID)
+	}
+	query += " ORDER BY timestamp " + dir + ", id " + dir
 	if limit > 0 {
 		query += " LIMIT ?"
 		args = append(args, limit)
+		if after == nil {
+			query += " OFFSET ?"
+			args = append(args, offset)
+		}
 	}
+	logs, err := m.queryLogs(query, args...)
+	return logs, total, err
+}
 
+func (m *Manager) queryLogs(query string, args ...interface{}) ([]LogEntry, error) {
 	rows, err := m.db.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query logs: %w", err)
@@ -345,49 +447,12 @@ func (m *Manager) Error(source, message string, metadata map[string]interface{})
 	m.Log(LogLevelError, source, message, metadata)
 }
 
-// logInterceptWriter implements io.Writer so that Go's standard log package
-// output is captured and routed through the logging manager.
-type logInterceptWriter struct {
-	manager *Manager
-}
-
-// Write implements io.Writer. It parses "[Component] message" format from
-// standard log.Printf calls and routes them into the structured log system.
-func (w *logInterceptWriter) Write(p []byte) (n int, err error) {
-	msg := strings.TrimSpace(string(p))
-	// Strip the default log prefix (date/time) if present
-	// Standard log format: "2006/01/02 15:04:05 message"
-	if len(msg) > 20 && msg[4] == '/' && msg[7] == '/' && msg[10] == ' ' {
-		msg = strings.TrimSpace(msg[20:])
-	}
-
-	level := LogLevelInfo
-	source := "system"
-
-	// Detect level from content
-	lowerMsg := strings.ToLower(msg)
-	if strings.Contains(lowerMsg, "error") || strings.Contains(lowerMsg, "fail") {
-		level = LogLevelError
-	} else if strings.Contains(lowerMsg, "warn") {
-		level = LogLevelWarn
-	}
-
-	// Parse [Source] prefix: "[Dispatcher] message" → source=dispatcher
-	if len(msg) > 2 && msg[0] == '[' {
-		end := strings.Index(msg, "]")
-		if end > 1 {
-			source = strings.ToLower(msg[1:end])
-			msg = strings.TrimSpace(msg[end+1:])
-		}
-	}
-
-	w.manager.Log(level, source, msg, nil)
-	return len(p), nil
-}
-
-// InstallLogInterceptor redirects Go's standard log package through this manager.
+// InstallLogInterceptor copies the process's structured logs, including
+// standard log package output, into this manager for the log viewer.
 // Call this once at startup after creating the manager.
 func (m *Manager) InstallLogInterceptor() {
-	log.SetOutput(&logInterceptWriter{manager: m})
-	log.SetFlags(0) // We handle timestamps ourselves
+	output.mu.Lock()
+	output.manager = m
+	output.mu.Unlock()
+	installLegacyLog()
 }
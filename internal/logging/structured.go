@@ -0,0 +1,378 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output formats of the structured log
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// LevelSettings is the default log level and the per-module overrides
+type LevelSettings struct {
+	Default string            `json:"default"`
+	Modules map[string]string `json:"modules"`
+}
+
+// levelTable holds the minimum level of each module's logs
+type levelTable struct {
+	mu      sync.RWMutex
+	def     slog.Level
+	modules map[string]slog.Level
+}
+
+func (t *levelTable) enabled(module string, level slog.Level) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	floor, ok := t.modules[module]
+	if !ok {
+		floor = t.def
+	}
+	return level >= floor
+}
+
+// pipeline is where records go once a module logger lets them through: the
+// process output and, once installed, the log manager behind the UI
+type pipeline struct {
+	mu      sync.RWMutex
+	out     slog.Handler
+	manager *Manager
+}
+
+func (p *pipeline) emit(ctx context.Context, module string, r slog.Record) error {
+	p.mu.RLock()
+	out, manager := p.out, p.manager
+	p.mu.RUnlock()
+	if manager != nil {
+		manager.logRecord(module, r)
+	}
+	return out.Handle(ctx, r)
+}
+
+var (
+	levels = &levelTable{def: slog.LevelInfo, modules: map[string]slog.Level{}}
+	output = &pipeline{out: newOutputHandler(FormatJSON, os.Stderr)}
+
+	legacyOnce sync.Once
+)
+
+func newOutputHandler(format string, w io.Writer) slog.Handler {
+	// Module loggers filter by level before records get here
+	opts := &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: true}
+	if format == FormatText {
+		return slog.NewTextHandler(w, opts)
+	}
+	return slog.NewJSONHandler(w, opts)
+}
+
+// Configure sets the format, default level and per-module levels of the
+// process's logs and routes the standard log package and slog's default
+// logger through them. Modules without an override log at the default level.
+func Configure(format, level string, modules map[string]string) error {
+	switch format {
+	case "", FormatJSON, FormatText:
+	default:
+		return fmt.Errorf("invalid log format %q: must be json or text", format)
+	}
+	if level == "" {
+		level = LogLevelInfo
+	}
+	def, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	overrides := make(map[string]slog.Level, len(modules))
+	for module, l := range modules {
+		parsed, err := ParseLevel(l)
+		if err != nil {
+			return fmt.Errorf("module %s: %w", module, err)
+		}
+		overrides[module] = parsed
+	}
+
+	levels.mu.Lock()
+	levels.def = def
+	levels.modules = overrides
+	levels.mu.Unlock()
+
+	output.mu.Lock()
+	output.out = newOutputHandler(format, os.Stderr)
+	output.mu.Unlock()
+
+	installLegacyLog()
+	return nil
+}
+
+// ParseLevel parses debug, info, warn or error
+func ParseLevel(s string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case LogLevelDebug:
+		return slog.LevelDebug, nil
+	case LogLevelInfo:
+		return slog.LevelInfo, nil
+	case LogLevelWarn, "warning":
+		return slog.LevelWarn, nil
+	case LogLevelError:
+		return slog.LevelError, nil
+	}
+	return 0, fmt.Errorf("invalid log level %q: must be debug, info, warn or error", s)
+}
+
+// levelName maps a slog level onto the manager's level names
+func levelName(l slog.Level) string {
+	switch {
+	case l >= slog.LevelError:
+		return LogLevelError
+	case l >= slog.LevelWarn:
+		return LogLevelWarn
+	case l >= slog.LevelInfo:
+		return LogLevelInfo
+	}
+	return LogLevelDebug
+}
+
+// Levels returns the default level and the per-module overrides
+func Levels() LevelSettings {
+	levels.mu.RLock()
+	defer levels.mu.RUnlock()
+	settings := LevelSettings{Default: levelName(levels.def), Modules: make(map[string]string, len(levels.modules))}
+	for module, l := range levels.modules {
+		settings.Modules[module] = levelName(l)
+	}
+	return settings
+}
+
+// SetDefaultLevel changes the level of modules without an override
+func SetDefaultLevel(level string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels.mu.Lock()
+	levels.def = l
+	levels.mu.Unlock()
+	return nil
+}
+
+// SetModuleLevel overrides one module's level at runtime. An empty level
+// removes the override so the module follows the default again.
+func SetModuleLevel(module, level string) error {
+	module = strings.ToLower(strings.TrimSpace(module))
+	if module == "" {
+		return fmt.Errorf("module must not be empty")
+	}
+	levels.mu.Lock()
+	defer levels.mu.Unlock()
+	if level == "" {
+		delete(levels.modules, module)
+		return nil
+	}
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levels.modules[module] = l
+	return nil
+}
+
+// Logger returns the structured logger of a module such as "dispatch" or
+// "git". Its records carry the module name and any fields attached to the
+// context with WithFields, and its level follows the module's override.
+func Logger(module string) *slog.Logger {
+	return slog.New(&moduleHandler{module: module})
+}
+
+type fieldsKey struct{}
+
+// WithFields returns a context whose log records carry the given key/value
+// pairs, such as request_id, bead_id or agent_id, in addition to any the
+// context already carries. A key the context already carries is replaced.
+func WithFields(ctx context.Context, args ...any) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r := slog.NewRecord(time.Time{}, 0, "", 0)
+	r.Add(args...)
+	added := make([]slog.Attr, 0, r.NumAttrs())
+	keys := make(map[string]bool, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		added = append(added, a)
+		keys[a.Key] = true
+		return true
+	})
+	var fields []slog.Attr
+	for _, a := range Fields(ctx) {
+		if !keys[a.Key] {
+			fields = append(fields, a)
+		}
+	}
+	return context.WithValue(ctx, fieldsKey{}, append(fields, added...))
+}
+
+// Fields returns the log fields attached to ctx
+func Fields(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return fields
+}
+
+// moduleHandler filters records by its module's level and adds the module
+// name and context fields before passing them on to the pipeline
+type moduleHandler struct {
+	module string
+	attrs  []slog.Attr
+	groups []string
+}
+
+func (h *moduleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return levels.enabled(h.module, level)
+}
+
+func (h *moduleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if !levels.enabled(h.module, r.Level) {
+		return nil
+	}
+	out := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	if h.module != "" {
+		out.AddAttrs(slog.String("module", h.module))
+	}
+	out.AddAttrs(Fields(ctx)...)
+	out.AddAttrs(h.attrs...)
+	var own []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		own = append(own, a)
+		return true
+	})
+	out.AddAttrs(nest(h.groups, own)...)
+	return output.emit(ctx, h.module, out)
+}
+
+func (h *moduleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append(append([]slog.Attr{}, h.attrs...), nest(h.groups, attrs)...)
+	return &c
+}
+
+func (h *moduleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.groups = append(append([]string{}, h.groups...), name)
+	return &c
+}
+
+// nest wraps attrs in the handler's open groups, innermost last
+func nest(groups []string, attrs []slog.Attr) []slog.Attr {
+	if len(attrs) == 0 {
+		return nil
+	}
+	for i := len(groups) - 1; i >= 0; i-- {
+		args := make([]any, len(attrs))
+		for j, a := range attrs {
+			args[j] = a
+		}
+		attrs = []slog.Attr{slog.Group(groups[i], args...)}
+	}
+	return attrs
+}
+
+// flatten turns a record's attributes into manager metadata, joining group
+// names to keys with dots
+func flatten(prefix string, attrs []slog.Attr, into map[string]interface{}) {
+	for _, a := range attrs {
+		key := a.Key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		v := a.Value.Resolve()
+		if v.Kind() == slog.KindGroup {
+			flatten(key, v.Group(), into)
+			continue
+		}
+		into[key] = v.Any()
+	}
+}
+
+// logRecord copies a structured record into the manager so it shows up in
+// the log viewer, keyed by module and with its fields as metadata
+func (m *Manager) logRecord(module string, r slog.Record) {
+	var attrs []slog.Attr
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != "module" {
+			attrs = append(attrs, a)
+		}
+		return true
+	})
+	var metadata map[string]interface{}
+	if len(attrs) > 0 {
+		metadata = make(map[string]interface{}, len(attrs))
+		flatten("", attrs, metadata)
+	}
+	if module == "" {
+		module = "system"
+	}
+	m.Log(levelName(r.Level), module, r.Message, metadata)
+}
+
+// installLegacyLog routes slog's default logger and the standard log
+// package through the module loggers
+func installLegacyLog() {
+	legacyOnce.Do(func() {
+		slog.SetDefault(Logger(""))
+		log.SetOutput(legacyWriter{})
+		log.SetFlags(0)
+	})
+}
+
+// legacyWriter turns standard log lines into structured records. A
+// "[Component] message" prefix names the module, so per-module levels apply
+// to code that still calls log.Printf, and the level is guessed from the text.
+type legacyWriter struct{}
+
+func (legacyWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimSpace(string(p))
+	// Strip the default log prefix (date/time) if present
+	// Standard log format: "2006/01/02 15:04:05 message"
+	if len(msg) > 20 && msg[4] == '/' && msg[7] == '/' && msg[10] == ' ' {
+		msg = strings.TrimSpace(msg[20:])
+	}
+
+	level := slog.LevelInfo
+	lowerMsg := strings.ToLower(msg)
+	if strings.Contains(lowerMsg, "error") || strings.Contains(lowerMsg, "fail") {
+		level = slog.LevelError
+	} else if strings.Contains(lowerMsg, "warn") {
+		level = slog.LevelWarn
+	}
+
+	// Parse [Source] prefix: "[Dispatcher] message" → module=dispatcher
+	module := ""
+	if len(msg) > 2 && msg[0] == '[' {
+		end := strings.Index(msg, "]")
+		if end > 1 {
+			module = strings.ToLower(msg[1:end])
+			msg = strings.TrimSpace(msg[end+1:])
+		}
+	}
+
+	// skip [runtime.Callers, legacyWriter.Write, log.Logger.output, log.Printf]
+	var pcs [1]uintptr
+	runtime.Callers(4, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	h := &moduleHandler{module: module}
+	return len(p), h.Handle(context.Background(), r)
+}
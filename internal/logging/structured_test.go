@@ -0,0 +1,185 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureOutput sends the structured log to a buffer and resets the levels
+// for the duration of a test
+func captureOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	output.mu.Lock()
+	prevOut := output.out
+	output.out = slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	output.mu.Unlock()
+
+	levels.mu.Lock()
+	prevDef, prevModules := levels.def, levels.modules
+	levels.def, levels.modules = slog.LevelInfo, map[string]slog.Level{}
+	levels.mu.Unlock()
+
+	t.Cleanup(func() {
+		output.mu.Lock()
+		output.out = prevOut
+		output.mu.Unlock()
+		levels.mu.Lock()
+		levels.def, levels.modules = prevDef, prevModules
+		levels.mu.Unlock()
+	})
+	return &buf
+}
+
+func records(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	t.Helper()
+	var out []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("record %q is not JSON: %v", line, err)
+		}
+		out = append(out, rec)
+	}
+	return out
+}
+
+func TestLogger_ModuleAndContextFields(t *testing.T) {
+	buf := captureOutput(t)
+
+	ctx := WithFields(context.Background(), "request_id", "req-1", "bead_id", "b-1")
+	ctx = WithFields(ctx, "bead_id", "b-2", "agent_id", "a-1")
+	Logger("dispatch").InfoContext(ctx, "assigned", "attempt", 2)
+
+	recs := records(t, buf)
+	if len(recs) != 1 {
+		t.Fatalf("got %d records, want 1", len(recs))
+	}
+	rec := recs[0]
+	want := map[string]interface{}{
+		"msg": "assigned", "module": "dispatch", "request_id": "req-1",
+		"bead_id": "b-2", "agent_id": "a-1", "attempt": float64(2),
+	}
+	for k, v := range want {
+		if rec[k] != v {
+			t.Errorf("%s = %v, want %v", k, rec[k], v)
+		}
+	}
+}
+
+func TestLogger_PerModuleLevels(t *testing.T) {
+	buf := captureOutput(t)
+
+	if err := SetModuleLevel("dispatch", "debug"); err != nil {
+		t.Fatalf("SetModuleLevel: %v", err)
+	}
+	if err := SetModuleLevel("git", "error"); err != nil {
+		t.Fatalf("SetModuleLevel: %v", err)
+	}
+	Logger("dispatch").Debug("dispatch debug")
+	Logger("git").Warn("git warn")
+	Logger("provider").Debug("provider debug")
+	Logger("provider").Info("provider info")
+
+	var msgs []string
+	for _, rec := range records(t, buf) {
+		msgs = append(msgs, rec["msg"].(string))
+	}
+	if strings.Join(msgs, ",") != "dispatch debug,provider info" {
+		t.Errorf("logged %v, want dispatch debug and provider info", msgs)
+	}
+
+	settings := Levels()
+	if settings.Default != "info" || settings.Modules["dispatch"] != "debug" || settings.Modules["git"] != "error" {
+		t.Errorf("Levels() = %+v", settings)
+	}
+
+	// Clearing an override returns the module to the default
+	if err := SetModuleLevel("dispatch", ""); err != nil {
+		t.Fatalf("SetModuleLevel: %v", err)
+	}
+	if Logger("dispatch").Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("dispatch debug still enabled after clearing its override")
+	}
+	if _, ok := Levels().Modules["dispatch"]; ok {
+		t.Error("cleared override still listed")
+	}
+}
+
+func TestSetModuleLevel_Invalid(t *testing.T) {
+	captureOutput(t)
+
+	if err := SetModuleLevel("dispatch", "loud"); err == nil {
+		t.Error("expected an error for an unknown level")
+	}
+	if err := SetModuleLevel("", "debug"); err == nil {
+		t.Error("expected an error for an empty module")
+	}
+	if err := SetDefaultLevel("verbose"); err == nil {
+		t.Error("expected an error for an unknown default level")
+	}
+}
+
+func TestLegacyWriter_ParsesComponentAndLevel(t *testing.T) {
+	buf := captureOutput(t)
+
+	logger := log.New(legacyWriter{}, "", 0)
+	logger.Printf("[Dispatcher] Failed to claim bead %s", "b-1")
+	logger.Printf("plain message")
+
+	recs := records(t, buf)
+	if len(recs) != 2 {
+		t.Fatalf("got %d records, want 2", len(recs))
+	}
+	if recs[0]["module"] != "dispatcher" || recs[0]["level"] != "ERROR" || recs[0]["msg"] != "Failed to claim bead b-1" {
+		t.Errorf("first record = %v", recs[0])
+	}
+	if _, ok := recs[1]["module"]; ok || recs[1]["level"] != "INFO" {
+		t.Errorf("second record = %v", recs[1])
+	}
+
+	// Legacy lines follow their component's level too
+	if err := SetModuleLevel("dispatcher", "error"); err != nil {
+		t.Fatalf("SetModuleLevel: %v", err)
+	}
+	buf.Reset()
+	logger.Printf("[Dispatcher] Assigned bead")
+	if buf.Len() != 0 {
+		t.Errorf("info line logged despite the error level: %s", buf.String())
+	}
+}
+
+func TestManagerReceivesStructuredRecords(t *testing.T) {
+	captureOutput(t)
+	m := NewManager(nil)
+	output.mu.Lock()
+	prev := output.manager
+	output.manager = m
+	output.mu.Unlock()
+	defer func() {
+		output.mu.Lock()
+		output.manager = prev
+		output.mu.Unlock()
+	}()
+
+	ctx := WithFields(context.Background(), "bead_id", "b-1")
+	Logger("git").WarnContext(ctx, "push failed", slog.Group("remote", "name", "origin"))
+
+	entries := m.GetRecent(10, "", "git", "", "b-1", "", time.Time{}, time.Time{})
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+	e := entries[0]
+	if e.Level != LogLevelWarn || e.Message != "push failed" || e.Metadata["remote.name"] != "origin" {
+		t.Errorf("entry = %+v", e)
+	}
+}
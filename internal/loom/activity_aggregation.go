@@ -0,0 +1,27 @@
+package loom
+
+import (
+	"context"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// activityAggregationRules are the built-in aggregation rules with the
+// configured ones in place of those for the same event types
+func activityAggregationRules(cfg map[string]config.ActivityAggregationRule) map[string]activity.AggregationRule {
+	rules := activity.DefaultAggregationRules()
+	for eventType, r := range cfg {
+		rules[eventType] = activity.AggregationRule{Window: r.Window, GroupBy: r.GroupBy, MaxEvents: r.MaxEvents}
+	}
+	return rules
+}
+
+// reloadActivityAggregation applies changed aggregation rules to activities
+// recorded from now on
+func (a *Loom) reloadActivityAggregation(ctx context.Context, old, cfg *config.Config) error {
+	if a.activityManager != nil {
+		a.activityManager.SetAggregationRules(activityAggregationRules(cfg.Activity.Aggregation))
+	}
+	return nil
+}
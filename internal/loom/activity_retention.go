@@ -0,0 +1,71 @@
+package loom
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/reports"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newActivityRetention returns the activity feed's retention job as
+// configured, or nil when retention is off or there is no database.
+// Exports go to the archive directory and S3 bucket that are set; S3 needs
+// AWS credentials in the environment.
+func newActivityRetention(db *database.Database, cfg config.ActivityRetentionConfig) *activity.Retention {
+	if db == nil || !cfg.Enabled {
+		return nil
+	}
+	rc := activity.DefaultRetentionConfig()
+	if cfg.RawRetention > 0 {
+		rc.RawRetention = cfg.RawRetention
+	}
+	if cfg.RollupRetention > 0 {
+		rc.RollupRetention = cfg.RollupRetention
+	}
+	rc.Interval = cfg.Interval
+	rc.BatchSize = cfg.BatchSize
+
+	var archivers []activity.Archiver
+	if cfg.ArchiveDir != "" {
+		archivers = append(archivers, activity.DirArchiver(cfg.ArchiveDir))
+	}
+	if s3 := cfg.ArchiveS3; s3 != nil {
+		deliverer := reports.NewS3DelivererFromEnv()
+		if deliverer == nil {
+			// Deleting events that were meant to be exported loses them
+			log.Printf("[Activity] Retention disabled: archive_s3 is set but AWS credentials are not")
+			return nil
+		}
+		archivers = append(archivers, &s3ActivityArchiver{
+			s3:   deliverer,
+			dest: reports.Destination{Type: reports.DestinationS3, Bucket: s3.Bucket, Prefix: s3.Prefix, Region: s3.Region, Endpoint: s3.Endpoint},
+		})
+	}
+	return activity.NewRetention(db, rc, archivers...)
+}
+
+// s3ActivityArchiver uploads activity exports to a bucket, under its
+// prefix
+type s3ActivityArchiver struct {
+	s3   *reports.S3Deliverer
+	dest reports.Destination
+}
+
+// Archive implements activity.Archiver
+func (s *s3ActivityArchiver) Archive(ctx context.Context, name string, data []byte) error {
+	key := name
+	if p := strings.Trim(s.dest.Prefix, "/"); p != "" {
+		key = p + "/" + name
+	}
+	return s.s3.Put(ctx, s.dest, key, "application/x-ndjson", data)
+}
+
+// GetActivityRetention returns the activity feed's retention job, or nil
+// when retention is off
+func (a *Loom) GetActivityRetention() *activity.Retention {
+	return a.activityRetention
+}
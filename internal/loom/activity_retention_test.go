@@ -0,0 +1,106 @@
+package loom
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+func TestActivityRetention_ArchivesAndCompacts(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	defer db.Close()
+
+	old := time.Now().Add(-40 * 24 * time.Hour)
+	add := func(id, aggKey string, count int, ts time.Time) {
+		t.Helper()
+		if err := db.CreateActivity(&database.Activity{
+			ID: id, EventType: "bead.created", Timestamp: ts, Source: "test", ActorID: "agent-1",
+			Action: "created", ResourceType: "bead", ResourceID: "b-" + id, MetadataJSON: `{"title":"Fix it"}`,
+			AggregationKey: aggKey, AggregationCount: count, IsAggregated: aggKey != "", Visibility: "project",
+		}); err != nil {
+			t.Fatalf("CreateActivity failed: %v", err)
+		}
+	}
+	add("a1", "bead.created.hour.agent-1", 3, old)
+	add("a2", "", 1, old.Add(time.Minute))
+	add("a3", "", 1, old.Add(2*time.Minute))
+	add("recent", "", 1, time.Now())
+
+	dir := t.TempDir()
+	retention := newActivityRetention(db, config.ActivityRetentionConfig{Enabled: true, ArchiveDir: dir, BatchSize: 2})
+	if retention == nil {
+		t.Fatal("expected retention to be enabled")
+	}
+	report, err := retention.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Archived != 3 || report.Compacted != 3 || len(report.Files) != 2 {
+		t.Errorf("unexpected report %+v", report)
+	}
+
+	// Every expired event was exported, metadata included, before deletion
+	var exported []activity.Activity
+	for _, name := range report.Files {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile failed: %v", err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var a activity.Activity
+			if err := json.Unmarshal(scanner.Bytes(), &a); err != nil {
+				t.Fatalf("invalid JSONL line %q: %v", scanner.Text(), err)
+			}
+			exported = append(exported, a)
+		}
+	}
+	if len(exported) != 3 || exported[0].ID != "a1" || exported[0].Metadata["title"] != "Fix it" {
+		t.Errorf("unexpected export %+v", exported)
+	}
+
+	left, _ := db.ListActivities(database.ActivityFilters{})
+	if len(left) != 1 || left[0].ID != "recent" {
+		t.Errorf("expected only the recent activity to be kept, got %d", len(left))
+	}
+	rollups, err := retention.Rollups(database.ActivityRollupFilters{})
+	if err != nil {
+		t.Fatalf("Rollups failed: %v", err)
+	}
+	counts := make(map[string]int)
+	for _, r := range rollups {
+		counts[r.AggregationKey] += r.EventCount
+	}
+	if len(rollups) != 2 || counts["bead.created.hour.agent-1"] != 3 || counts[""] != 2 {
+		t.Errorf("unexpected rollups %+v", rollups)
+	}
+}
+
+func TestActivityRetention_Disabled(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	defer db.Close()
+
+	if newActivityRetention(db, config.ActivityRetentionConfig{}) != nil {
+		t.Error("expected retention to be off unless enabled")
+	}
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	if newActivityRetention(db, config.ActivityRetentionConfig{Enabled: true, ArchiveS3: &config.ActivityArchiveS3{Bucket: "b"}}) != nil {
+		t.Error("expected retention to stay off when its S3 archive cannot be written")
+	}
+}
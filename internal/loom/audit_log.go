@@ -0,0 +1,45 @@
+package loom
+
+import (
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+// newAuditLogger opens the central audit log on the database. Without a
+// database, or one that lacks the audit table, there is nowhere to keep the
+// chain and audit.Record is a no-op.
+func newAuditLogger(db *database.Database) *audit.Logger {
+	if db == nil {
+		return nil
+	}
+	logger, err := audit.NewLogger(db)
+	if err != nil {
+		log.Printf("Warning: audit log unavailable: %v", err)
+		return nil
+	}
+	return logger
+}
+
+// GetAuditLogger returns the central audit log
+func (a *Loom) GetAuditLogger() *audit.Logger {
+	return a.auditLogger
+}
+
+// auditDecision records the resolution of a decision bead
+func (a *Loom) auditDecision(decisionID, deciderID, decisionText, rationale string) {
+	ev := audit.Event{
+		Actor:    deciderID,
+		Action:   "decision.resolve",
+		Resource: decisionID,
+		Details: map[string]interface{}{
+			"decision":  decisionText,
+			"rationale": rationale,
+		},
+	}
+	if d, err := a.decisionManager.GetDecision(decisionID); err == nil && d != nil {
+		ev.ProjectID = d.ProjectID
+	}
+	audit.Record(ev)
+}
@@ -0,0 +1,30 @@
+package loom
+
+import (
+	"github.com/jordanhubbard/loom/internal/backup"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// NewBackups returns the backup manager for db as configured, or nil without
+// a database. The key store is added with SetKeystore once it is unlocked.
+func NewBackups(db *database.Database, cfg config.BackupConfig) *backup.Manager {
+	if db == nil {
+		return nil
+	}
+	bc := backup.DefaultConfig()
+	if cfg.Dir != "" {
+		bc.Dir = cfg.Dir
+	}
+	if cfg.Keep > 0 {
+		bc.Keep = cfg.Keep
+	}
+	bc.Interval = cfg.Interval
+	bc.MaxAge = cfg.MaxAge
+	return backup.NewManager(db, bc)
+}
+
+// GetBackups returns the backup manager, or nil without a database
+func (a *Loom) GetBackups() *backup.Manager {
+	return a.backups
+}
@@ -0,0 +1,69 @@
+package loom
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/internal/worker"
+)
+
+// beadGuidance passes the comments humans post on a bead to the agent
+// working on it
+type beadGuidance struct {
+	loom *Loom
+}
+
+// PendingGuidance returns the bead's comments posted after since
+func (g *beadGuidance) PendingGuidance(beadID string, since time.Time) ([]worker.Guidance, error) {
+	if g.loom.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	comments, err := g.loom.database.GetCommentsByBeadID(beadID)
+	if err != nil {
+		return nil, err
+	}
+	var out []worker.Guidance
+	for _, c := range comments {
+		if !c.CreatedAt.After(since) {
+			continue
+		}
+		out = append(out, worker.Guidance{
+			CommentID: c.ID,
+			Author:    c.AuthorUsername,
+			Content:   c.Content,
+			CreatedAt: c.CreatedAt,
+		})
+	}
+	return out, nil
+}
+
+// AcknowledgeGuidance puts the agent's taking in of comments in the
+// activity feed
+func (g *beadGuidance) AcknowledgeGuidance(projectID, beadID, agentID string, iteration int, guidance []worker.Guidance) {
+	if g.loom.eventBus == nil {
+		return
+	}
+	ids := make([]string, len(guidance))
+	authors := make([]string, 0, len(guidance))
+	seen := make(map[string]bool)
+	for i, c := range guidance {
+		ids[i] = c.CommentID
+		if !seen[c.Author] {
+			seen[c.Author] = true
+			authors = append(authors, c.Author)
+		}
+	}
+	data := map[string]interface{}{
+		"agent_id":    agentID,
+		"actor_id":    agentID,
+		"actor_type":  "agent",
+		"iteration":   iteration,
+		"comment_ids": ids,
+		"authors":     authors,
+	}
+	if bead, err := g.loom.beadsManager.GetBead(beadID); err == nil && bead != nil {
+		data["title"] = bead.Title
+	}
+	_ = g.loom.eventBus.PublishBeadEvent(eventbus.EventTypeBeadGuidanceAcked, beadID, projectID, data)
+}
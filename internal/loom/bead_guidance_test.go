@@ -0,0 +1,41 @@
+package loom
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+func TestBeadGuidance_PendingGuidanceReturnsLaterComments(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	since := time.Now()
+	for _, c := range []struct {
+		id string
+		at time.Time
+	}{
+		{"c-before", since.Add(-time.Minute)},
+		{"c-after", since.Add(time.Second)},
+	} {
+		if err := l.database.CreateComment(&database.BeadComment{
+			ID: c.id, BeadID: "bead-1", AuthorID: "u1", AuthorUsername: "alice",
+			Content: "Use the v2 API.", CreatedAt: c.at, UpdatedAt: c.at,
+		}); err != nil {
+			t.Fatalf("CreateComment failed: %v", err)
+		}
+	}
+
+	g := &beadGuidance{loom: l}
+	pending, err := g.PendingGuidance("bead-1", since)
+	if err != nil {
+		t.Fatalf("PendingGuidance failed: %v", err)
+	}
+	if len(pending) != 1 || pending[0].CommentID != "c-after" || pending[0].Author != "alice" {
+		t.Errorf("expected only the later comment, got %+v", pending)
+	}
+	// Acknowledging guidance on a bead the tracker does not know is harmless
+	g.AcknowledgeGuidance("proj-1", "bead-1", "agent-1", 2, pending)
+}
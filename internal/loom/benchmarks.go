@@ -0,0 +1,89 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newBenchmarks opens the provider benchmark over db. Every run's measured
+// latency and cost go into the provider scorer. Without a database there
+// is nowhere to keep runs.
+func newBenchmarks(db *database.Database, registry *provider.Registry, cfg config.BenchmarkConfig) *benchmark.Manager {
+	if db == nil || registry == nil {
+		return nil
+	}
+	mgr := benchmark.NewManager(db, &benchmarkCompleter{registry: registry}, benchmark.Config{
+		Interval:    cfg.Interval,
+		Timeout:     cfg.Timeout,
+		ProviderIDs: cfg.ProviderIDs,
+	})
+	mgr.OnRun(func(run *benchmark.Run) {
+		for _, s := range run.Providers {
+			log.Printf("[Benchmark] %s (%s): %d/%d passed, %.0fms average, $%.4f", s.ProviderID, s.Model, s.Passed, s.Tasks, s.AvgLatencyMs, s.CostUSD)
+			if s.Answered > 0 {
+				registry.RecordBenchmark(s.ProviderID, s.AvgLatencyMs, s.CostPerMToken)
+			}
+		}
+	})
+	return mgr
+}
+
+// GetBenchmarks returns the provider benchmark
+func (a *Loom) GetBenchmarks() *benchmark.Manager {
+	return a.benchmarks
+}
+
+// benchmarkCompleter runs benchmark tasks on the registered providers
+type benchmarkCompleter struct {
+	registry *provider.Registry
+}
+
+// Targets returns the active providers and their models. The demo's
+// scripted provider cannot answer the tasks, so it is left out.
+func (c *benchmarkCompleter) Targets() []benchmark.Target {
+	var targets []benchmark.Target
+	for _, p := range c.registry.ListActive() {
+		if p == nil || p.Config == nil || slices.Contains(p.Config.Tags, provider.DemoTag) {
+			continue
+		}
+		targets = append(targets, benchmark.Target{ProviderID: p.Config.ID, Model: p.Config.Model})
+	}
+	return targets
+}
+
+// Complete sends one task to a provider's current model and prices the
+// tokens it took
+func (c *benchmarkCompleter) Complete(ctx context.Context, providerID, systemPrompt, prompt string) (benchmark.Completion, error) {
+	out := benchmark.Completion{}
+	if p, err := c.registry.Get(providerID); err == nil && p.Config != nil {
+		out.Model = p.Config.Model
+	}
+	resp, err := c.registry.SendChatCompletion(provider.WithPriority(ctx, provider.PriorityP3), providerID, &provider.ChatCompletionRequest{
+		Model: out.Model,
+		Messages: []provider.ChatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+	})
+	if err != nil {
+		return out, err
+	}
+	if resp.Model != "" {
+		out.Model = resp.Model
+	}
+	out.PromptTokens = resp.Usage.PromptTokens
+	out.CompletionTokens = resp.Usage.CompletionTokens
+	out.CostUSD = c.registry.EstimateCost(providerID, out.Model, out.PromptTokens, out.CompletionTokens)
+	if len(resp.Choices) == 0 {
+		return out, fmt.Errorf("provider %s returned no choices", providerID)
+	}
+	out.Response = resp.Choices[0].Message.Content
+	return out, nil
+}
@@ -0,0 +1,38 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestBenchmarks_FeedProviderScores(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	if l.GetBenchmarks() == nil {
+		t.Fatal("expected the benchmark to be configured")
+	}
+
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "demo-mock", Type: "mock", Status: "active", Tags: []string{provider.DemoTag}}, &reviewProtocol{})
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "bench", Type: "mock", Status: "active", Model: "review-model"}, &reviewProtocol{answer: "391"})
+	if _, ok := l.providerRegistry.GetScorer().GetScore("bench"); ok {
+		t.Fatal("expected no score before the benchmark")
+	}
+
+	run, err := l.GetBenchmarks().Run(context.Background(), benchmark.TriggerManual, nil, "")
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(run.Providers) != 1 || run.Providers[0].ProviderID != "bench" {
+		t.Fatalf("expected only the non-demo provider benchmarked, got %+v", run.Providers)
+	}
+	if s := run.Providers[0]; s.Passed != 1 || s.Answered != s.Tasks {
+		t.Errorf("expected only the arithmetic task to pass, got %+v", s)
+	}
+	if _, ok := l.providerRegistry.GetScorer().GetScore("bench"); !ok {
+		t.Error("expected the benchmark to score the provider")
+	}
+}
@@ -0,0 +1,120 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/chat"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newChatManager keeps the read-only chat sessions with projects' agents
+func newChatManager(cfg config.ChatConfig) *chat.Manager {
+	return chat.NewManager(chat.Config{
+		MaxToolRounds: cfg.MaxToolRounds,
+		IdleTimeout:   cfg.IdleTimeout,
+		MaxSessions:   cfg.MaxSessions,
+	})
+}
+
+// StartChat opens a read-only chat of a user with a project's agent
+func (a *Loom) StartChat(projectID, userID string) (*chat.Session, error) {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("project not found: %s", projectID)
+	}
+	name := p.Name
+	if name == "" {
+		name = p.ID
+	}
+	return a.chats.Start(p.ID, userID, chat.SystemPrompt(name, p.GitRepo, p.Branch)), nil
+}
+
+// ChatSession returns a user's chat session with a project's agent
+func (a *Loom) ChatSession(projectID, sessionID, userID string) (*chat.Session, error) {
+	s, ok := a.chats.Get(sessionID)
+	if !ok || s.ProjectID != projectID || s.UserID != userID {
+		return nil, fmt.Errorf("chat session not found: %s", sessionID)
+	}
+	return s, nil
+}
+
+// EndChat drops a user's chat session with a project's agent
+func (a *Loom) EndChat(projectID, sessionID, userID string) error {
+	if _, err := a.ChatSession(projectID, sessionID, userID); err != nil {
+		return err
+	}
+	a.chats.Delete(sessionID)
+	return nil
+}
+
+// AskChat answers a message in a chat session with the project's agent,
+// on a provider the project's compliance constraints and organization
+// allow. The agent can only read the project. onEvent, when not nil, is
+// told of each step and of the reply as it streams.
+func (a *Loom) AskChat(ctx context.Context, s *chat.Session, message string, onEvent func(chat.Event)) (*chat.Reply, error) {
+	rp, err := a.chatProvider(s.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	var exec chat.Executor
+	if a.actionRouter != nil {
+		exec = a.actionRouter
+	}
+	return a.chats.Ask(ctx, s, message, a.chatCompleter(rp), exec, onEvent)
+}
+
+// chatProvider picks the first active provider allowed to serve a project
+func (a *Loom) chatProvider(projectID string) (*provider.RegisteredProvider, error) {
+	for _, rp := range a.providerRegistry.ListActive() {
+		if rp != nil && a.providerServesProject(rp.Config, projectID) {
+			return rp, nil
+		}
+	}
+	return nil, fmt.Errorf("no active provider may serve project %s", projectID)
+}
+
+// providerServesProject reports whether a project's compliance constraints
+// and organization allow a provider to serve it
+func (a *Loom) providerServesProject(cfg *provider.ProviderConfig, projectID string) bool {
+	return dispatch.ProviderServesProject(cfg, a.ProjectCompliance(projectID), org.Normalize(a.ProjectOrg(projectID)))
+}
+
+// chatCompleter sends chat conversations to a provider's current model,
+// streaming replies when asked to and the provider can
+func (a *Loom) chatCompleter(rp *provider.RegisteredProvider) chat.Completer {
+	return func(ctx context.Context, messages []provider.ChatMessage, onChunk func(string)) (string, error) {
+		// Someone is waiting on the reply: skip ahead of agent work
+		ctx = provider.WithPriority(ctx, provider.PriorityP0)
+		req := &provider.ChatCompletionRequest{Model: rp.Config.Model, Messages: messages, Temperature: 0.2}
+		if _, ok := rp.Protocol.(provider.StreamingProtocol); ok && onChunk != nil {
+			req.Stream = true
+			var sb strings.Builder
+			err := a.providerRegistry.SendChatCompletionStream(ctx, rp.Config.ID, req, func(chunk *provider.StreamChunk) error {
+				if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+					sb.WriteString(chunk.Choices[0].Delta.Content)
+					onChunk(chunk.Choices[0].Delta.Content)
+				}
+				return nil
+			})
+			return sb.String(), err
+		}
+
+		resp, err := a.providerRegistry.SendChatCompletion(ctx, rp.Config.ID, req)
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 {
+			return "", fmt.Errorf("provider %s returned no choices", rp.Config.ID)
+		}
+		text := resp.Choices[0].Message.Content
+		if onChunk != nil {
+			onChunk(text)
+		}
+		return text, nil
+	}
+}
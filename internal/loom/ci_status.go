@@ -0,0 +1,226 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/cistatus"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/memory"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// Bead context keys written by CI tracking
+const (
+	beadCIStateKey  = "ci_state"
+	beadCIBranchKey = "ci_branch"
+	beadCISHAKey    = "ci_sha"
+	beadCIChecksKey = "ci_checks" // JSON of the checks on the head commit
+)
+
+// newCIStatus opens CI tracking over db. Status changes are surfaced on the
+// bead working the branch, and repeated failures become lessons. Without a
+// database there is nowhere to keep statuses.
+func newCIStatus(a *Loom, db *database.Database, cfg *config.Config) *cistatus.Manager {
+	if db == nil {
+		return nil
+	}
+	extractor := memory.NewExtractor(db, memory.NewHashEmbedder())
+	mgr := cistatus.NewManager(db, &ciPoller{loom: a}, cistatus.Config{
+		Enabled:         cfg.CI.Enabled,
+		RequiredChecks:  cfg.CI.RequiredChecks,
+		PollInterval:    cfg.CI.PollInterval,
+		LessonThreshold: cfg.CI.LessonThreshold,
+	})
+	for _, p := range cfg.Projects {
+		if p.RequiredChecks != nil {
+			mgr.SetRequiredChecks(p.ID, p.RequiredChecks)
+		}
+	}
+
+	mgr.OnChange(a.ciStatusChanged)
+	mgr.OnRepeatedFailure(func(s *cistatus.Status) {
+		failures := make([]memory.CIFailure, 0, len(s.Failures))
+		for _, f := range s.Failures {
+			failures = append(failures, memory.CIFailure{SHA: f.SHA, Checks: f.Checks})
+		}
+		extractor.ExtractFromCI(s.ProjectID, s.BeadID, s.Branch, failures)
+	})
+	return mgr
+}
+
+// GetCIStatus returns CI tracking for bead branches
+func (a *Loom) GetCIStatus() *cistatus.Manager {
+	return a.ciStatus
+}
+
+// ciStatusChanged records a branch's new CI state on the bead working it
+// and publishes it
+func (a *Loom) ciStatusChanged(s *cistatus.Status, previous string) {
+	if s.BeadID == "" {
+		return
+	}
+	bead, err := a.beadsManager.GetBead(s.BeadID)
+	if err != nil {
+		return
+	}
+	checksJSON, _ := json.Marshal(s.Checks)
+	if err := a.beadsManager.UpdateBead(s.BeadID, map[string]interface{}{
+		"context": map[string]string{
+			beadCIStateKey:  s.State,
+			beadCIBranchKey: s.Branch,
+			beadCISHAKey:    s.SHA,
+			beadCIChecksKey: string(checksJSON),
+		},
+	}); err != nil {
+		log.Printf("[CI] Failed to record CI state on bead %s: %v", s.BeadID, err)
+	}
+
+	if a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadCIStatus, s.BeadID, s.ProjectID, map[string]interface{}{
+			"title":          bead.Title,
+			"branch":         s.Branch,
+			"sha":            s.SHA,
+			"state":          s.State,
+			"previous_state": previous,
+			"failed_checks":  s.Failed(),
+		})
+	}
+}
+
+// BranchPushed satisfies actions.CIGate: branches agents push are tracked
+func (a *Loom) BranchPushed(ctx context.Context, actx actions.ActionContext, branch string) {
+	if branch == "" || !a.ciStatus.Enabled() {
+		return
+	}
+	if _, err := a.ciStatus.Track(actx.ProjectID, actx.BeadID, branch); err != nil {
+		log.Printf("[CI] Failed to track %s in %s: %v", branch, actx.ProjectID, err)
+	}
+}
+
+// CheckBranch satisfies actions.CIGate: a branch is held back until its
+// required checks pass. Demo projects have no CI.
+func (a *Loom) CheckBranch(ctx context.Context, actx actions.ActionContext, branch string) error {
+	if !a.ciStatus.Enabled() || (a.demo != nil && a.demo.HandlesProject(actx.ProjectID)) {
+		return nil
+	}
+	if branch == "" {
+		statuses, err := a.ciStatus.ForBead(actx.ProjectID, actx.BeadID)
+		if err != nil || len(statuses) == 0 {
+			return err
+		}
+		branch = statuses[0].Branch
+	}
+	return a.ciStatus.Gate(ctx, actx.ProjectID, branch)
+}
+
+// ReportCIChecks records check results a git host sent for a branch of
+// repository, an owner/name path. The bead is taken from agent branch
+// names.
+func (a *Loom) ReportCIChecks(ctx context.Context, repository, branch, sha string, checks []cistatus.Check, complete bool) (*cistatus.Status, error) {
+	if !a.ciStatus.Enabled() {
+		return nil, nil
+	}
+	projectID := a.projectForRepository(repository)
+	if projectID == "" {
+		return nil, fmt.Errorf("no project for repository %s", repository)
+	}
+	return a.ciStatus.Report(ctx, cistatus.Report{
+		ProjectID: projectID,
+		Branch:    branch,
+		BeadID:    a.beadForBranch(branch),
+		SHA:       sha,
+		Checks:    checks,
+		Complete:  complete,
+	})
+}
+
+// projectForRepository finds the project whose git remote is repository,
+// an owner/name path such as GitHub's full_name
+func (a *Loom) projectForRepository(repository string) string {
+	repository = strings.ToLower(strings.Trim(repository, "/"))
+	if repository == "" || a.projectManager == nil {
+		return ""
+	}
+	for _, p := range a.projectManager.ListProjects() {
+		remote := strings.ToLower(strings.TrimSuffix(strings.TrimRight(p.GitRepo, "/"), ".git"))
+		if strings.HasSuffix(remote, "/"+repository) || strings.HasSuffix(remote, ":"+repository) {
+			return p.ID
+		}
+	}
+	return ""
+}
+
+// beadForBranch returns the bead of an agent/<bead-id>/<description>
+// branch, if it exists
+func (a *Loom) beadForBranch(branch string) string {
+	rest, ok := strings.CutPrefix(branch, "agent/")
+	if !ok {
+		return ""
+	}
+	beadID, _, _ := strings.Cut(rest, "/")
+	if _, err := a.beadsManager.GetBead(beadID); err != nil {
+		return ""
+	}
+	return beadID
+}
+
+// ciPoller reads the check runs on a branch from GitHub through the gh CLI.
+// Demo projects report no checks.
+type ciPoller struct {
+	loom *Loom
+}
+
+// Checks returns the head commit of branch and its check runs
+func (p *ciPoller) Checks(ctx context.Context, projectID, branch string) (string, []cistatus.Check, error) {
+	a := p.loom
+	if a.demo != nil && a.demo.HandlesProject(projectID) {
+		return "", nil, nil
+	}
+	res, err := a.ExecuteCommand(ctx, executor.ExecuteCommandRequest{
+		AgentID:   "ci-status",
+		ProjectID: projectID,
+		Command:   fmt.Sprintf("gh api repos/{owner}/{repo}/commits/%s/check-runs", url.PathEscape(branch)),
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	if !res.Success {
+		msg := strings.TrimSpace(res.Stderr)
+		if msg == "" {
+			msg = res.Error
+		}
+		return "", nil, fmt.Errorf("gh failed: %s", msg)
+	}
+
+	var body struct {
+		CheckRuns []struct {
+			Name       string `json:"name"`
+			HeadSHA    string `json:"head_sha"`
+			Status     string `json:"status"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+		} `json:"check_runs"`
+	}
+	if err := json.Unmarshal([]byte(res.Stdout), &body); err != nil {
+		return "", nil, fmt.Errorf("failed to decode check runs: %w", err)
+	}
+	sha := ""
+	checks := make([]cistatus.Check, 0, len(body.CheckRuns))
+	for _, run := range body.CheckRuns {
+		sha = run.HeadSHA
+		checks = append(checks, cistatus.Check{
+			Name:  run.Name,
+			State: cistatus.GitHubCheckState(run.Status, run.Conclusion),
+			URL:   run.HTMLURL,
+		})
+	}
+	return sha, checks, nil
+}
@@ -0,0 +1,71 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/cistatus"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestReportCIChecks_SurfacesStatusOnBead(t *testing.T) {
+	l, tmpDir := testLoom(t, func(cfg *config.Config) {
+		cfg.CI = config.CIConfig{Enabled: true, RequiredChecks: []string{"test"}}
+	})
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+	ctx := context.Background()
+
+	proj, err := l.CreateProject("ci", "git@github.com:acme/widgets.git", "main", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	bead, err := l.CreateBead("Add login", "desc", models.BeadPriorityP2, "task", proj.ID)
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	branch := "agent/" + bead.ID + "/add-login"
+	actx := actions.ActionContext{ProjectID: proj.ID, BeadID: bead.ID}
+
+	if _, err := l.ReportCIChecks(ctx, "unknown/repo", branch, "a1", nil, false); err == nil {
+		t.Error("expected checks for an unknown repository to be refused")
+	}
+
+	s, err := l.ReportCIChecks(ctx, "Acme/Widgets", branch, "a1", []cistatus.Check{{Name: "test", State: cistatus.StateFailure}}, false)
+	if err != nil {
+		t.Fatalf("ReportCIChecks failed: %v", err)
+	}
+	if s.ProjectID != proj.ID || s.BeadID != bead.ID {
+		t.Fatalf("expected the status to be matched to the project and bead, got %+v", s)
+	}
+	got, _ := l.beadsManager.GetBead(bead.ID)
+	if got.Context[beadCIStateKey] != cistatus.StateFailure || got.Context[beadCIBranchKey] != branch || got.Context[beadCISHAKey] != "a1" {
+		t.Fatalf("CI state not recorded on the bead: %v", got.Context)
+	}
+	var checks []cistatus.Check
+	if err := json.Unmarshal([]byte(got.Context[beadCIChecksKey]), &checks); err != nil || len(checks) != 1 {
+		t.Errorf("checks not recorded on the bead: %v", got.Context[beadCIChecksKey])
+	}
+
+	// Without a branch the bead's latest branch is gated
+	if err := l.CheckBranch(ctx, actx, ""); err == nil || !strings.Contains(err.Error(), "CI failed") {
+		t.Errorf("expected the failing branch to be held back, got %v", err)
+	}
+
+	if _, err := l.ReportCIChecks(ctx, "acme/widgets", branch, "b2", []cistatus.Check{{Name: "test", State: cistatus.StateSuccess}}, true); err != nil {
+		t.Fatalf("ReportCIChecks failed: %v", err)
+	}
+	if err := l.CheckBranch(ctx, actx, branch); err != nil {
+		t.Errorf("expected the passing branch to go through, got %v", err)
+	}
+	got, _ = l.beadsManager.GetBead(bead.ID)
+	if got.Context[beadCIStateKey] != cistatus.StateSuccess {
+		t.Errorf("expected the bead to show passing CI, got %v", got.Context)
+	}
+}
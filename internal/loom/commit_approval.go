@@ -0,0 +1,383 @@
+package loom
+
+import (
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Project context keys of commit approval
+const (
+	// commitApprovalKey, set to "true", holds the project's agent commits
+	// until their diffs are approved
+	commitApprovalKey = "commit_approval"
+	// commitLowRiskGlobsKey lists, comma-separated, the file globs whose
+	// patches may be approved in a batch
+	commitLowRiskGlobsKey = "commit_approval_low_risk_globs"
+)
+
+// patchDecisionKey is the decision context key naming the patch a decision
+// approves or rejects
+const patchDecisionKey = "patch_id"
+
+// patchPreviewLimit is how much of a patch's diff its decision shows
+const patchPreviewLimit = 4000
+
+// commitGate holds agent commits in projects requiring commit approval.
+// The staged diff is stored as a proposed patch and a decision is opened
+// on the bead, blocking it; approving either the patch or the decision
+// lets a commit of the same diff through.
+type commitGate struct {
+	loom *Loom
+}
+
+// ReviewCommit lets the commit through in projects without commit
+// approval and for approved patches, and otherwise proposes the patch
+func (g *commitGate) ReviewCommit(projectID string, staged *git.StagedPatch) (bool, string, string) {
+	a := g.loom
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil || p == nil || !strings.EqualFold(p.Context[commitApprovalKey], "true") {
+		return true, "", ""
+	}
+	if a.database == nil {
+		return false, "", "the project requires commit approval and there is no database to hold the patch"
+	}
+
+	existing, err := a.database.FindProposedPatch(projectID, staged.Fingerprint)
+	if err != nil {
+		log.Printf("[CommitApproval] Failed to find patch: %v", err)
+		return false, "", "the patch could not be looked up; try again"
+	}
+	if existing != nil {
+		if existing.Status == models.PatchStatusPending {
+			existing = g.syncDecision(existing)
+		}
+		switch existing.Status {
+		case models.PatchStatusApproved:
+			audit.Record(audit.Event{
+				Actor:     staged.AgentID,
+				Action:    "git.patch_committed",
+				Resource:  existing.ID,
+				ProjectID: projectID,
+				Outcome:   audit.OutcomeSuccess,
+				Details:   map[string]interface{}{"bead_id": staged.BeadID, "approved_by": existing.ReviewedBy},
+			})
+			return true, "", ""
+		case models.PatchStatusRejected:
+			return false, existing.ID, existing.ReviewReason
+		}
+		return false, existing.ID, ""
+	}
+
+	patch, err := g.propose(p, staged)
+	if err != nil {
+		log.Printf("[CommitApproval] Failed to propose patch: %v", err)
+		return false, "", "the patch could not be proposed; try again"
+	}
+	return false, patch.ID, ""
+}
+
+// propose stores a staged diff as a pending patch and opens its decision
+func (g *commitGate) propose(p *models.Project, staged *git.StagedPatch) (*models.ProposedPatch, error) {
+	a := g.loom
+	patch := &models.ProposedPatch{
+		ID:          "patch-" + uuid.New().String()[:8],
+		ProjectID:   p.ID,
+		BeadID:      staged.BeadID,
+		AgentID:     staged.AgentID,
+		Message:     staged.Message,
+		Files:       staged.Files,
+		Diff:        staged.Diff,
+		Fingerprint: staged.Fingerprint,
+		LowRisk:     patchMatchesGlobs(staged.Files, lowRiskGlobs(p)),
+		Status:      models.PatchStatusPending,
+		CreatedAt:   time.Now().UTC(),
+	}
+	if err := a.database.CreateProposedPatch(patch); err != nil {
+		return nil, err
+	}
+
+	preview := patch.Diff
+	if len(preview) > patchPreviewLimit {
+		preview = preview[:patchPreviewLimit] + "\n... (truncated; GET /api/v1/projects/" + p.ID + "/patches/" + patch.ID + " for the full diff)"
+	}
+	question := fmt.Sprintf("Agent %s wants to commit to project %s (bead %s):\n\n%s\n\nFiles: %s\n\n```diff\n%s\n```\n\nChoose: approve | reject",
+		patch.AgentID, p.ID, patch.BeadID, patch.Message, strings.Join(patch.Files, ", "), preview)
+	d, err := a.CreateDecisionBead(question, patch.BeadID, "system", []string{"approve", "reject"}, "", models.BeadPriorityP1, p.ID)
+	if err != nil {
+		log.Printf("[CommitApproval] Failed to open decision for patch %s: %v", patch.ID, err)
+	} else {
+		_ = a.decisionManager.UpdateDecisionContext(d.ID, map[string]string{patchDecisionKey: patch.ID})
+		if err := a.database.SetProposedPatchDecision(patch.ID, d.ID); err == nil {
+			patch.DecisionID = d.ID
+		}
+	}
+
+	audit.Record(audit.Event{
+		Actor:     patch.AgentID,
+		Action:    "git.patch_proposed",
+		Resource:  patch.ID,
+		ProjectID: p.ID,
+		Outcome:   audit.OutcomeSuccess,
+		Details:   map[string]interface{}{"bead_id": patch.BeadID, "files": patch.Files, "low_risk": patch.LowRisk, "decision_id": patch.DecisionID},
+	})
+	if a.eventBus != nil && patch.BeadID != "" {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadPatchProposed, patch.BeadID, p.ID, map[string]interface{}{
+			"patch_id":    patch.ID,
+			"agent_id":    patch.AgentID,
+			"actor_id":    patch.AgentID,
+			"actor_type":  "agent",
+			"files":       patch.Files,
+			"low_risk":    patch.LowRisk,
+			"decision_id": patch.DecisionID,
+		})
+	}
+	return patch, nil
+}
+
+// syncDecision applies a decision on the patch made outside the patch API
+func (g *commitGate) syncDecision(patch *models.ProposedPatch) *models.ProposedPatch {
+	if patch.DecisionID == "" {
+		return patch
+	}
+	d, err := g.loom.decisionManager.GetDecision(patch.DecisionID)
+	if err != nil || d == nil || strings.TrimSpace(d.Decision) == "" {
+		return patch
+	}
+	g.loom.applyPatchDecision(d)
+	if updated, err := g.loom.database.GetProposedPatch(patch.ID); err == nil && updated != nil {
+		return updated
+	}
+	return patch
+}
+
+// lowRiskGlobs returns the project's low-risk file globs
+func lowRiskGlobs(p *models.Project) []string {
+	var globs []string
+	for _, g := range strings.Split(p.Context[commitLowRiskGlobsKey], ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			globs = append(globs, g)
+		}
+	}
+	return globs
+}
+
+// patchMatchesGlobs reports whether every file matches one of the globs. A
+// glob ending in "/**" matches everything under its directory, and a glob
+// without a slash matches file names in any directory.
+func patchMatchesGlobs(files, globs []string) bool {
+	if len(files) == 0 || len(globs) == 0 {
+		return false
+	}
+	for _, f := range files {
+		matched := false
+		for _, g := range globs {
+			if matchPatchGlob(g, f) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func matchPatchGlob(glob, file string) bool {
+	if dir, ok := strings.CutSuffix(glob, "/**"); ok {
+		return strings.HasPrefix(file, dir+"/")
+	}
+	if !strings.Contains(glob, "/") {
+		file = path.Base(file)
+	}
+	ok, _ := path.Match(glob, file)
+	return ok
+}
+
+// ListPatches returns a project's proposed patches in the given status, or
+// in any status when it is empty, newest first
+func (a *Loom) ListPatches(projectID string, status models.PatchStatus) ([]*models.ProposedPatch, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	return a.database.ListProposedPatches(projectID, status)
+}
+
+// GetPatch returns a proposed patch with its diff
+func (a *Loom) GetPatch(id string) (*models.ProposedPatch, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	patch, err := a.database.GetProposedPatch(id)
+	if err != nil {
+		return nil, err
+	}
+	if patch == nil {
+		return nil, fmt.Errorf("patch %s not found", id)
+	}
+	return patch, nil
+}
+
+// ApprovePatch approves a pending patch; the bead's agent is dispatched
+// again to commit it
+func (a *Loom) ApprovePatch(id, userID string) (*models.ProposedPatch, error) {
+	return a.reviewPatch(id, userID, models.PatchStatusApproved, "")
+}
+
+// RejectPatch rejects a pending patch; the bead's agent is dispatched again
+// to rework its changes, told the reason
+func (a *Loom) RejectPatch(id, userID, reason string) (*models.ProposedPatch, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to reject a patch")
+	}
+	return a.reviewPatch(id, userID, models.PatchStatusRejected, reason)
+}
+
+// ApproveLowRiskPatches approves every pending patch of a project whose
+// files all match the globs, or the project's low-risk globs when none are
+// given
+func (a *Loom) ApproveLowRiskPatches(projectID, userID string, globs []string) ([]*models.ProposedPatch, error) {
+	if len(globs) == 0 {
+		p, err := a.projectManager.GetProject(projectID)
+		if err != nil {
+			return nil, fmt.Errorf("project not found: %w", err)
+		}
+		globs = lowRiskGlobs(p)
+	}
+	if len(globs) == 0 {
+		return nil, fmt.Errorf("no low-risk globs are configured for project %s; set %s or pass globs", projectID, commitLowRiskGlobsKey)
+	}
+	pending, err := a.ListPatches(projectID, models.PatchStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	approved := []*models.ProposedPatch{}
+	for _, patch := range pending {
+		if !patchMatchesGlobs(patch.Files, globs) {
+			continue
+		}
+		reviewed, err := a.reviewPatch(patch.ID, userID, models.PatchStatusApproved, "batch approval of low-risk files")
+		if err != nil {
+			log.Printf("[CommitApproval] Failed to approve patch %s: %v", patch.ID, err)
+			continue
+		}
+		approved = append(approved, reviewed)
+	}
+	return approved, nil
+}
+
+func (a *Loom) reviewPatch(id, userID string, status models.PatchStatus, reason string) (*models.ProposedPatch, error) {
+	patch, err := a.GetPatch(id)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Status != models.PatchStatusPending {
+		return nil, fmt.Errorf("patch %s is already %s", id, patch.Status)
+	}
+	now := time.Now().UTC()
+	ok, err := a.database.ReviewProposedPatch(id, status, userID, reason, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("patch %s is already reviewed", id)
+	}
+	patch.Status, patch.ReviewedBy, patch.ReviewReason, patch.ReviewedAt = status, userID, reason, &now
+	a.patchReviewed(patch)
+
+	// Resolve the patch's decision too, which unblocks the bead
+	if patch.DecisionID != "" {
+		decision, rationale := "approve", "patch approved"
+		if status == models.PatchStatusRejected {
+			decision, rationale = "reject", reason
+		}
+		decider := userID
+		if !strings.HasPrefix(decider, "user-") {
+			decider = "user-" + decider
+		}
+		if d, err := a.decisionManager.GetDecision(patch.DecisionID); err == nil && d != nil && d.Decision == "" {
+			if err := a.MakeDecision(patch.DecisionID, decider, decision, rationale); err != nil {
+				log.Printf("[CommitApproval] Failed to resolve decision %s: %v", patch.DecisionID, err)
+			}
+		}
+	}
+	return patch, nil
+}
+
+// applyPatchDecision reviews the patch of a decided patch decision, when
+// the decision was made rather than the patch reviewed
+func (a *Loom) applyPatchDecision(d *models.DecisionBead) {
+	patchID := d.Context[patchDecisionKey]
+	if patchID == "" || a.database == nil {
+		return
+	}
+	status := models.PatchStatusRejected
+	if strings.EqualFold(strings.TrimSpace(d.Decision), "approve") {
+		status = models.PatchStatusApproved
+	}
+	reason := d.Rationale
+	if status == models.PatchStatusRejected && reason == "" {
+		reason = d.Decision
+	}
+	now := time.Now().UTC()
+	ok, err := a.database.ReviewProposedPatch(patchID, status, d.DeciderID, reason, now)
+	if err != nil || !ok {
+		return
+	}
+	if patch, err := a.database.GetProposedPatch(patchID); err == nil && patch != nil {
+		a.patchReviewed(patch)
+	}
+}
+
+// patchReviewed tells the bead's agent of the review and redispatches the
+// bead to act on it
+func (a *Loom) patchReviewed(patch *models.ProposedPatch) {
+	eventType, action := eventbus.EventTypeBeadPatchApproved, "git.patch_approved"
+	if patch.Status == models.PatchStatusRejected {
+		eventType, action = eventbus.EventTypeBeadPatchRejected, "git.patch_rejected"
+	}
+	audit.Record(audit.Event{
+		Actor:     patch.ReviewedBy,
+		Action:    action,
+		Resource:  patch.ID,
+		ProjectID: patch.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details:   map[string]interface{}{"bead_id": patch.BeadID, "reason": patch.ReviewReason, "files": patch.Files},
+	})
+	if patch.BeadID == "" {
+		return
+	}
+
+	ctx := map[string]string{
+		"commit_patch":         patch.ID,
+		"commit_patch_status":  string(patch.Status),
+		"redispatch_requested": "true",
+	}
+	if patch.Status == models.PatchStatusApproved {
+		ctx["commit_patch_review"] = "approved; commit the staged changes again to record them"
+	} else {
+		ctx["commit_patch_review"] = "rejected: " + patch.ReviewReason
+	}
+	if _, err := a.UpdateBead(patch.BeadID, map[string]interface{}{"context": ctx}); err != nil {
+		log.Printf("[CommitApproval] Failed to update bead %s: %v", patch.BeadID, err)
+	}
+	if a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventType, patch.BeadID, patch.ProjectID, map[string]interface{}{
+			"patch_id":    patch.ID,
+			"actor_id":    patch.ReviewedBy,
+			"actor_type":  "user",
+			"reviewed_by": patch.ReviewedBy,
+			"reason":      patch.ReviewReason,
+		})
+	}
+}
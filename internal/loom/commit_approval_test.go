@@ -0,0 +1,87 @@
+package loom
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestCommitGate_HoldsUntilApproved(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	proj, err := l.CreateProject("gated", ".", "", "", map[string]string{
+		commitApprovalKey:     "true",
+		commitLowRiskGlobsKey: "docs/**, *.md",
+	})
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	g := &commitGate{loom: l}
+	docs := &git.StagedPatch{AgentID: "agent-1", Message: "Update guide", Files: []string{"docs/guide.md", "README.md"}, Diff: "+docs\n", Fingerprint: "fp-docs"}
+	code := &git.StagedPatch{AgentID: "agent-1", Message: "Fix auth", Files: []string{"internal/auth.go"}, Diff: "+code\n", Fingerprint: "fp-code"}
+
+	for _, staged := range []*git.StagedPatch{docs, code} {
+		approved, patchID, _ := g.ReviewCommit(proj.ID, staged)
+		if approved || patchID == "" {
+			t.Fatalf("expected %s held as a patch, got approved=%v id=%q", staged.Fingerprint, approved, patchID)
+		}
+	}
+	if approved, _, _ := g.ReviewCommit(proj.ID, docs); approved {
+		t.Fatal("expected a pending patch to stay held")
+	}
+
+	batch, err := l.ApproveLowRiskPatches(proj.ID, "u-1", nil)
+	if err != nil || len(batch) != 1 || batch[0].Fingerprint != "fp-docs" {
+		t.Fatalf("ApproveLowRiskPatches = %+v, %v", batch, err)
+	}
+	if approved, _, _ := g.ReviewCommit(proj.ID, docs); !approved {
+		t.Error("expected the approved patch to be committed")
+	}
+
+	pending, _ := l.ListPatches(proj.ID, models.PatchStatusPending)
+	if len(pending) != 1 || pending[0].LowRisk || pending[0].DecisionID == "" {
+		t.Fatalf("expected the code patch pending with a decision, got %+v", pending)
+	}
+	if _, err := l.RejectPatch(pending[0].ID, "u-1", ""); err == nil {
+		t.Error("expected a rejection without a reason to fail")
+	}
+	if _, err := l.RejectPatch(pending[0].ID, "u-1", "keep the old token format"); err != nil {
+		t.Fatalf("RejectPatch failed: %v", err)
+	}
+	if d, err := l.decisionManager.GetDecision(pending[0].DecisionID); err != nil || d.Decision != "reject" {
+		t.Errorf("expected the patch's decision resolved, got %+v (%v)", d, err)
+	}
+	if approved, _, rejection := g.ReviewCommit(proj.ID, code); approved || rejection != "keep the old token format" {
+		t.Errorf("expected the rejected patch refused with its reason, got approved=%v rejection=%q", approved, rejection)
+	}
+
+	other, err := l.CreateProject("ungated", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if approved, _, _ := g.ReviewCommit(other.ID, code); !approved {
+		t.Error("expected commits in a project without commit approval to go through")
+	}
+}
+
+func TestPatchMatchesGlobs(t *testing.T) {
+	globs := []string{"docs/**", "*.md", "web/*.css"}
+	tests := []struct {
+		files []string
+		want  bool
+	}{
+		{[]string{"docs/a/b.txt", "CHANGELOG.md", "pkg/README.md"}, true},
+		{[]string{"web/site.css"}, true},
+		{[]string{"web/js/site.css"}, false},
+		{[]string{"docs/guide.md", "main.go"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := patchMatchesGlobs(tt.files, globs); got != tt.want {
+			t.Errorf("patchMatchesGlobs(%v) = %v, want %v", tt.files, got, tt.want)
+		}
+	}
+}
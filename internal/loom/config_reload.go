@@ -0,0 +1,334 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/backup"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/logging"
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/internal/temporal/workflows"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// ReloadHook applies a reloaded section of the configuration to the running
+// server. old is the configuration in effect before the reload.
+type ReloadHook func(ctx context.Context, old, cfg *config.Config) error
+
+// reloadSection is a part of the configuration that can change without a
+// restart
+type reloadSection struct {
+	path  string // Dotted setting path, e.g. "dispatch" or "agents.max_concurrent"
+	apply ReloadHook
+}
+
+// ReloadResult reports a configuration reload
+type ReloadResult struct {
+	Source          string            `json:"source"` // The config file, or "configuration" when there is none
+	Actor           string            `json:"actor"`
+	Changes         []config.Change   `json:"changes"`
+	Reloaded        []string          `json:"reloaded"`                   // Sections applied
+	Failed          map[string]string `json:"failed,omitempty"`           // Sections whose hook failed, with the error; they keep their old values
+	RestartRequired []string          `json:"restart_required,omitempty"` // Changed settings that only take effect on restart
+	ReloadedAt      time.Time         `json:"reloaded_at"`
+}
+
+// SetConfigPath names the file the configuration was loaded from, which
+// ReloadConfig reads again. Empty means defaults and environment only.
+func (a *Loom) SetConfigPath(path string) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	a.configPath = path
+}
+
+// RegisterReloadHook makes the setting at path reloadable, replacing any
+// hook already registered for it. The hook runs when a reload changes a
+// setting at or below path.
+func (a *Loom) RegisterReloadHook(path string, hook ReloadHook) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	for i := range a.reloadSections {
+		if a.reloadSections[i].path == path {
+			a.reloadSections[i].apply = hook
+			return
+		}
+	}
+	a.reloadSections = append(a.reloadSections, reloadSection{path: path, apply: hook})
+}
+
+// ReloadableSections returns the settings ReloadConfig applies
+func (a *Loom) ReloadableSections() []string {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	paths := make([]string, 0, len(a.reloadSections))
+	for _, s := range a.reloadSections {
+		paths = append(paths, s.path)
+	}
+	return paths
+}
+
+// LastConfigReload returns the result of the most recent reload, or nil
+func (a *Loom) LastConfigReload() *ReloadResult {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+	return a.lastReload
+}
+
+// ReloadConfig reads the configuration again and applies the reloadable
+// sections that changed. An invalid configuration is rejected and nothing
+// changes. Other changed settings are reported as needing a restart. The
+// reload and its changes are recorded in the audit log.
+func (a *Loom) ReloadConfig(ctx context.Context, actor string) (*ReloadResult, error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	source := a.configPath
+	if source == "" {
+		source = "configuration"
+	}
+	cfg, err := config.Load(a.configPath)
+	if err == nil && len(cfg.SecretRefs()) > 0 {
+		// secret:NAME settings resolve against the key store, as at startup
+		if a.keyManager == nil || !a.keyManager.IsUnlocked() {
+			err = fmt.Errorf("secret references need an unlocked key store")
+		} else {
+			err = cfg.ResolveSecrets(a.keyManager.GetKey)
+		}
+	}
+	if err != nil {
+		audit.Record(audit.Event{
+			Actor:   actor,
+			Action:  "config.reload",
+			Outcome: audit.OutcomeFailure,
+			Details: map[string]interface{}{"source": source, "error": err.Error()},
+		})
+		log.Printf("[Config] Reload rejected: %v", err)
+		return nil, err
+	}
+
+	result := &ReloadResult{Source: source, Actor: actor, Changes: config.Diff(a.config, cfg), Reloaded: []string{}, ReloadedAt: time.Now().UTC()}
+	changed := make(map[string]bool)
+	for _, c := range result.Changes {
+		if section := a.reloadSectionOf(c.Field); section != "" {
+			changed[section] = true
+		} else {
+			result.RestartRequired = append(result.RestartRequired, c.Field)
+		}
+	}
+
+	for _, s := range a.reloadSections {
+		if !changed[s.path] {
+			continue
+		}
+		err := s.apply(ctx, a.config, cfg)
+		if err == nil {
+			err = config.CopySetting(a.config, cfg, s.path)
+		}
+		if err != nil {
+			if result.Failed == nil {
+				result.Failed = make(map[string]string)
+			}
+			result.Failed[s.path] = err.Error()
+			log.Printf("[Config] Failed to reload %s: %v", s.path, err)
+			continue
+		}
+		result.Reloaded = append(result.Reloaded, s.path)
+	}
+	a.lastReload = result
+
+	ev := audit.Event{
+		Actor:   actor,
+		Action:  "config.reload",
+		Outcome: audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"source":   source,
+			"changes":  result.Changes,
+			"reloaded": result.Reloaded,
+		},
+	}
+	if len(result.Failed) > 0 {
+		ev.Outcome = audit.OutcomeFailure
+		ev.Details["failed"] = result.Failed
+	}
+	if len(result.RestartRequired) > 0 {
+		ev.Details["restart_required"] = result.RestartRequired
+	}
+	audit.Record(ev)
+
+	if a.eventBus != nil && len(result.Reloaded) > 0 {
+		_ = a.eventBus.Publish(&eventbus.Event{
+			Type:   eventbus.EventTypeConfigUpdated,
+			Source: "config-reload",
+			Data:   map[string]interface{}{"reloaded": result.Reloaded},
+		})
+	}
+	log.Printf("[Config] Reloaded %s: %d changes, reloaded %v, %d need a restart",
+		source, len(result.Changes), result.Reloaded, len(result.RestartRequired))
+	return result, nil
+}
+
+// reloadSectionOf returns the longest registered section containing the
+// setting at field, or "" if it is not reloadable
+func (a *Loom) reloadSectionOf(field string) string {
+	best := ""
+	for _, s := range a.reloadSections {
+		if field == s.path || strings.HasPrefix(field, s.path+".") || strings.HasPrefix(field, s.path+"[") {
+			if len(s.path) > len(best) {
+				best = s.path
+			}
+		}
+	}
+	return best
+}
+
+// registerReloadHooks registers the sections that are safe to change while
+// Loom runs: the provider list, dispatch concurrency and guardrails,
+// readiness gating, log levels, the backup schedule, activity policies,
+// activity feed aggregation and provider request queues
+func (a *Loom) registerReloadHooks() {
+	a.RegisterReloadHook("providers", a.reloadProviders)
+	a.RegisterReloadHook("agents.max_concurrent", func(ctx context.Context, old, cfg *config.Config) error {
+		if cfg.Agents.MaxConcurrent <= 0 {
+			return fmt.Errorf("agents.max_concurrent must be positive")
+		}
+		a.agentManager.SetMaxAgents(cfg.Agents.MaxConcurrent)
+		return nil
+	})
+	a.RegisterReloadHook("dispatch", func(ctx context.Context, old, cfg *config.Config) error {
+		a.dispatcher.SetMaxDispatchHops(cfg.Dispatch.MaxHops)
+		a.agentManager.SetMaxLoopResumes(cfg.Dispatch.MaxResumes)
+		a.agentManager.SetContextBudget(a.newContextBudget(cfg.Dispatch.ContextBudget))
+		return nil
+	})
+	a.RegisterReloadHook("readiness", func(ctx context.Context, old, cfg *config.Config) error {
+		a.dispatcher.SetReadinessMode(dispatch.ReadinessMode(cfg.Readiness.Mode))
+		return nil
+	})
+	a.RegisterReloadHook("logging", func(ctx context.Context, old, cfg *config.Config) error {
+		return logging.Configure(cfg.Logging.Format, cfg.Logging.Level, cfg.Logging.Modules)
+	})
+	a.RegisterReloadHook("backup", func(ctx context.Context, old, cfg *config.Config) error {
+		if cfg.Backup.Dir != old.Backup.Dir {
+			return fmt.Errorf("backup.dir only changes on restart")
+		}
+		keep := cfg.Backup.Keep
+		if keep <= 0 {
+			keep = backup.DefaultConfig().Keep
+		}
+		a.backups.SetSchedule(cfg.Backup.Interval, keep, cfg.Backup.MaxAge)
+		return nil
+	})
+	a.RegisterReloadHook("temporal.activities", func(ctx context.Context, old, cfg *config.Config) error {
+		// Activities already scheduled keep the policy they started under
+		workflows.SetActivityPolicies(cfg.Temporal.Activities)
+		if a.scheduler != nil {
+			a.registerSchedulerActivities(cfg)
+		}
+		return nil
+	})
+	a.RegisterReloadHook("activity.aggregation", a.reloadActivityAggregation)
+	a.RegisterReloadHook("models.queue", func(ctx context.Context, old, cfg *config.Config) error {
+		// Requests already waiting keep their place
+		a.providerRegistry.SetQueueConfig(providerQueueConfig(cfg.Models.Queue))
+		return nil
+	})
+	a.RegisterReloadHook("models.saturation", func(ctx context.Context, old, cfg *config.Config) error {
+		a.providerRegistry.SetSaturationConfig(providerSaturationConfig(cfg.Models.Saturation))
+		return nil
+	})
+	a.RegisterReloadHook("lessons.search", func(ctx context.Context, old, cfg *config.Config) error {
+		if a.database == nil {
+			return fmt.Errorf("database not configured")
+		}
+		a.database.SetLessonSearchConfig(lessonSearchConfig(cfg.Lessons.Search))
+		return nil
+	})
+}
+
+// reloadProviders registers providers added to the configuration, updates
+// those whose settings changed and removes those taken out or disabled.
+// Providers added through the API are left alone.
+func (a *Loom) reloadProviders(ctx context.Context, old, cfg *config.Config) error {
+	if a.database == nil {
+		return fmt.Errorf("database not configured")
+	}
+	before := make(map[string]config.Provider)
+	for _, p := range old.Providers {
+		if seed := seedProvider(p); seed != nil {
+			before[seed.ID] = p
+		}
+	}
+
+	var errs []string
+	after := make(map[string]bool)
+	for _, p := range cfg.Providers {
+		seed := seedProvider(p)
+		if seed == nil {
+			continue
+		}
+		after[seed.ID] = true
+		prev, ok := before[seed.ID]
+		if ok && prev == p {
+			continue
+		}
+		existing, err := a.database.GetProvider(seed.ID)
+		if err != nil || existing == nil {
+			_, err = a.RegisterProvider(ctx, seed)
+		} else {
+			existing.Name = seed.Name
+			existing.Type = seed.Type
+			existing.Endpoint = seed.Endpoint
+			existing.ConfiguredModel = seed.Model
+			existing.SelectedModel = ""
+			existing.RequiresKey = seed.RequiresKey
+			existing.Status = "pending"
+			_, err = a.UpdateProvider(ctx, existing)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", seed.ID, err))
+		}
+	}
+	for id := range before {
+		if after[id] {
+			continue
+		}
+		if err := a.DeleteProvider(ctx, id); err != nil {
+			log.Printf("[Config] Provider %s removed from the configuration but not deleted: %v", id, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("providers not applied: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// seedProvider is the provider an enabled entry of the configuration
+// registers, or nil if it registers none
+func seedProvider(p config.Provider) *internalmodels.Provider {
+	if !p.Enabled {
+		return nil
+	}
+	id := p.ID
+	if id == "" && p.Name != "" {
+		id = strings.ReplaceAll(strings.ToLower(p.Name), " ", "-")
+	}
+	if id == "" {
+		log.Printf("Skipping provider seed without id or name: endpoint=%s", p.Endpoint)
+		return nil
+	}
+	return &internalmodels.Provider{
+		ID:          id,
+		Name:        p.Name,
+		Type:        p.Type,
+		Endpoint:    p.Endpoint,
+		Model:       p.Model,
+		RequiresKey: p.APIKey != "",
+		Status:      "pending",
+	}
+}
@@ -0,0 +1,177 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/keymanager"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+const reloadBaseConfig = `
+database:
+  type: sqlite
+  path: ":memory:"
+git:
+  project_key_dir: %s
+temporal:
+  host: ""
+`
+
+func TestReloadConfig(t *testing.T) {
+	keyDir := t.TempDir()
+	t.Setenv("LOOM_AGENTS_DEFAULT_PERSONA_PATH", "../../personas")
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(extra string) {
+		t.Helper()
+		data := []byte(fmt.Sprintf(reloadBaseConfig, keyDir) + extra)
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(`
+providers:
+  - id: p1
+    type: openai
+    endpoint: http://127.0.0.1:1/v1
+    enabled: true
+`)
+	base, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	l, tmpDir := testLoom(t, func(c *config.Config) { *c = *base })
+	defer os.RemoveAll(tmpDir)
+	l.SetConfigPath(path)
+	ctx := context.Background()
+
+	write(`
+server:
+  http_port: 9999
+backup:
+  dir: elsewhere
+agents:
+  max_concurrent: 3
+dispatch:
+  max_hops: 7
+providers:
+  - id: p1
+    type: openai
+    endpoint: http://127.0.0.1:2/v1
+    enabled: true
+  - id: p2
+    type: openai
+    endpoint: http://127.0.0.1:3/v1
+    enabled: true
+`)
+	res, err := l.ReloadConfig(ctx, "admin")
+	if err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	reloaded := make(map[string]bool)
+	for _, s := range res.Reloaded {
+		reloaded[s] = true
+	}
+	if !reloaded["providers"] || !reloaded["agents.max_concurrent"] || !reloaded["dispatch"] || len(res.Reloaded) != 3 {
+		t.Errorf("reloaded %v", res.Reloaded)
+	}
+	if res.Failed["backup"] == "" {
+		t.Errorf("a changed backup.dir should fail to reload, got %v", res.Failed)
+	}
+	if len(res.RestartRequired) != 1 || res.RestartRequired[0] != "server.http_port" {
+		t.Errorf("restart required %v", res.RestartRequired)
+	}
+
+	if l.config.Dispatch.MaxHops != 7 || l.config.Agents.MaxConcurrent != 3 {
+		t.Errorf("reloaded settings not applied: dispatch %+v agents %+v", l.config.Dispatch, l.config.Agents)
+	}
+	if l.config.Server.HTTPPort == 9999 || l.config.Backup.Dir == "elsewhere" {
+		t.Error("settings that need a restart should keep their values")
+	}
+	if got := l.agentManager.GetWorkerPool().GetPoolStats().MaxWorkers; got != 3 {
+		t.Errorf("worker pool limit = %d, want 3", got)
+	}
+	for _, id := range []string{"p1", "p2"} {
+		p, err := l.database.GetProvider(id)
+		if err != nil || p == nil {
+			t.Fatalf("provider %s not registered: %v", id, err)
+		}
+		if id == "p1" && p.Endpoint != "http://127.0.0.1:2/v1" {
+			t.Errorf("p1 endpoint = %s", p.Endpoint)
+		}
+	}
+
+	entries, err := l.auditLogger.Query(audit.Filter{Action: "config.reload"})
+	if err != nil || len(entries) != 1 || entries[0].Actor != "admin" || entries[0].Details["changes"] == nil {
+		t.Fatalf("audit entries = %+v, %v", entries, err)
+	}
+
+	// Taking a provider out of the configuration removes it
+	write(`
+agents:
+  max_concurrent: 3
+dispatch:
+  max_hops: 7
+providers:
+  - id: p1
+    type: openai
+    endpoint: http://127.0.0.1:2/v1
+    enabled: true
+`)
+	if _, err := l.ReloadConfig(ctx, "admin"); err != nil {
+		t.Fatalf("ReloadConfig: %v", err)
+	}
+	if p, err := l.database.GetProvider("p2"); err == nil && p != nil {
+		t.Error("p2 should be removed")
+	}
+
+	// An invalid configuration changes nothing
+	last := l.LastConfigReload()
+	write("database:\n  type: mysql\ndispatch:\n  max_hops: 1\n")
+	if _, err := l.ReloadConfig(ctx, "admin"); err == nil {
+		t.Fatal("expected an invalid configuration to be rejected")
+	}
+	if l.config.Dispatch.MaxHops != 7 || l.LastConfigReload() != last {
+		t.Error("a rejected reload changed the configuration")
+	}
+	entries, _ = l.auditLogger.Query(audit.Filter{Action: "config.reload", Outcome: audit.OutcomeFailure})
+	if len(entries) != 2 {
+		t.Errorf("expected the failed backup reload and the rejection to be audited, got %d", len(entries))
+	}
+
+	// secret:NAME settings need the key store
+	write(`
+agents:
+  max_concurrent: 3
+dispatch:
+  max_hops: 7
+providers:
+  - id: p1
+    type: openai
+    endpoint: http://127.0.0.1:2/v1
+    api_key: secret:p1-key
+    enabled: true
+`)
+	if _, err := l.ReloadConfig(ctx, "admin"); err == nil {
+		t.Fatal("expected a secret reference to be rejected without a key store")
+	}
+	km := keymanager.NewKeyManager(filepath.Join(t.TempDir(), "keys.json"))
+	if err := km.Unlock("pw"); err != nil {
+		t.Fatal(err)
+	}
+	if err := km.StoreKey("p1-key", "p1-key", "", "sk-1"); err != nil {
+		t.Fatal(err)
+	}
+	l.SetKeyManager(km)
+	if _, err := l.ReloadConfig(ctx, "admin"); err != nil {
+		t.Fatalf("ReloadConfig with secrets: %v", err)
+	}
+	if got := l.config.Providers[0].APIKey; got != "sk-1" {
+		t.Errorf("api_key = %q, want the stored secret", got)
+	}
+}
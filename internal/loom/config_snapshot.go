@@ -147,7 +147,7 @@ func (a *Loom) ApplyConfigSnapshot(ctx context.Context, snap *ConfigSnapshot) er
 		if p == nil {
 			continue
 		}
-		p.Endpoint = normalizeProviderEndpoint(p.Endpoint)
+		p.Endpoint = registryEndpoint(p.Type, p.Endpoint)
 		if err := a.database.UpsertProvider(p); err != nil {
 			return err
 		}
@@ -239,9 +239,11 @@ func (a *Loom) ReloadFromDatabase(ctx context.Context) error {
 			ID:       p.ID,
 			Name:     p.Name,
 			Type:     p.Type,
-			Endpoint: normalizeProviderEndpoint(p.Endpoint),
+			Endpoint: registryEndpoint(p.Type, p.Endpoint),
 			APIKey:   "",
 			Model:    p.Model,
+			Tags:     p.Tags,
+			OrgID:    p.OrgID,
 		})
 	}
 
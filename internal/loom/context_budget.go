@@ -0,0 +1,44 @@
+package loom
+
+import (
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/worker"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newContextBudget builds the prompt budget workers size dispatch prompts
+// with, reading context windows from the provider registry's model
+// metadata and reporting cuts to the Prometheus metrics
+func (a *Loom) newContextBudget(cfg config.ContextBudgetConfig) *worker.ContextBudget {
+	b := &worker.ContextBudget{
+		DefaultWindow: cfg.DefaultContextWindow,
+		PromptShare:   cfg.PromptShare,
+		SectionShares: cfg.SectionShares,
+		Models:        make(map[string]worker.ModelBudget, len(cfg.Models)),
+	}
+	if a.providerRegistry != nil {
+		b.Metadata = a.providerRegistry
+	}
+	if a.metrics != nil {
+		b.Telemetry = a.metrics
+	}
+	checkPromptSections("dispatch.context_budget", cfg.SectionShares)
+	for _, m := range cfg.Models {
+		if m.Model == "" {
+			continue
+		}
+		checkPromptSections("dispatch.context_budget model "+m.Model, m.SectionShares)
+		b.Models[m.Model] = worker.ModelBudget{PromptShare: m.PromptShare, SectionShares: m.SectionShares}
+	}
+	return b
+}
+
+// checkPromptSections warns about section shares that cap nothing
+func checkPromptSections(where string, shares map[string]float64) {
+	for name := range shares {
+		if !worker.IsPromptSection(name) {
+			log.Printf("[PromptBudget] Warning: %s caps unknown section %q (sections: %v)", where, name, worker.PromptSections())
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/prreview"
+)
+
+// BeadCost returns what the requests made for a bead cost since it was
+// created, with the commits made for it and what it had cost when each of
+// its pull requests was opened
+func (a *Loom) BeadCost(ctx context.Context, beadID string) (*analytics.BeadCost, error) {
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return nil, err
+	}
+	logs, err := a.requestLogs(ctx, bead.CreatedAt, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+	cost := &analytics.BeadCost{BeadID: bead.ID}
+	for _, c := range analytics.AttributeCosts(logs) {
+		if c.BeadID == bead.ID {
+			cost = c
+		}
+	}
+	cost.ProjectID = bead.ProjectID
+
+	reviews, err := a.beadReviews(bead.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	a.joinGitMetadata(ctx, cost, logs, reviews[bead.ID])
+	return cost, nil
+}
+
+// BeadCosts returns what the requests made in [start, end) cost per bead,
+// highest first, each joined with the bead's commits and pull requests.
+// Costs cover the period alone, including the cost of a pull request,
+// which counts the requests of the period made before it was opened. An
+// empty projectID covers every project; a zero end means now.
+func (a *Loom) BeadCosts(ctx context.Context, projectID string, start, end time.Time) ([]*analytics.BeadCost, error) {
+	logs, err := a.requestLogs(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+	reviewsByProject := make(map[string]map[string][]*prreview.Review)
+	var costs []*analytics.BeadCost
+	for _, c := range analytics.AttributeCosts(logs) {
+		if projectID != "" && c.ProjectID != projectID {
+			continue
+		}
+		reviews, ok := reviewsByProject[c.ProjectID]
+		if !ok {
+			if reviews, err = a.beadReviews(c.ProjectID); err != nil {
+				return nil, err
+			}
+			reviewsByProject[c.ProjectID] = reviews
+		}
+		a.joinGitMetadata(ctx, c, logs, reviews[c.BeadID])
+		costs = append(costs, c)
+	}
+	return costs, nil
+}
+
+// PullRequestCost satisfies actions.PullRequestCoster: pull requests agents
+// open carry what their bead has cost so far
+func (a *Loom) PullRequestCost(ctx context.Context, actx actions.ActionContext) string {
+	if actx.BeadID == "" || a.database == nil {
+		return ""
+	}
+	cost, err := a.BeadCost(ctx, actx.BeadID)
+	if err != nil {
+		log.Printf("[Analytics] Failed to read the cost of bead %s: %v", actx.BeadID, err)
+		return ""
+	}
+	if cost.Requests == 0 {
+		return ""
+	}
+	return cost.Trailer()
+}
+
+// requestLogs returns the request logs from start until end, or until now
+// when end is zero. Logs end inclusively, so the last instant is dropped.
+func (a *Loom) requestLogs(ctx context.Context, start, end time.Time) ([]*analytics.RequestLog, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("analytics unavailable: no database configured")
+	}
+	storage, err := analytics.NewDatabaseStorage(a.database.DB())
+	if err != nil {
+		return nil, err
+	}
+	logs, err := storage.GetLogs(ctx, &analytics.LogFilter{StartTime: start, EndTime: end})
+	if err != nil || end.IsZero() {
+		return logs, err
+	}
+	out := logs[:0]
+	for _, l := range logs {
+		if l.Timestamp.Before(end) {
+			out = append(out, l)
+		}
+	}
+	return out, nil
+}
+
+// beadReviews returns a project's pull request reviews by bead, or nothing
+// without the review stage
+func (a *Loom) beadReviews(projectID string) (map[string][]*prreview.Review, error) {
+	byBead := make(map[string][]*prreview.Review)
+	if a.prReviews == nil || projectID == "" {
+		return byBead, nil
+	}
+	reviews, err := a.prReviews.List(projectID, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reviews {
+		if r.BeadID != "" {
+			byBead[r.BeadID] = append(byBead[r.BeadID], r)
+		}
+	}
+	return byBead, nil
+}
+
+// joinGitMetadata adds the commits made for a bead, read from its
+// project's git history, and its pull requests, each with what the bead
+// had cost in logs when the pull request was first reviewed
+func (a *Loom) joinGitMetadata(ctx context.Context, cost *analytics.BeadCost, logs []*analytics.RequestLog, reviews []*prreview.Review) {
+	if a.actionRouter != nil && a.actionRouter.Git != nil && cost.ProjectID != "" {
+		result, err := a.actionRouter.Git.GetBeadCommits(actions.WithProjectID(ctx, cost.ProjectID), cost.BeadID)
+		if err != nil {
+			log.Printf("[Analytics] Failed to read commits of bead %s: %v", cost.BeadID, err)
+		}
+		commits, _ := result["commits"].([]git.CommitMetadata)
+		for _, c := range commits {
+			cost.Commits = append(cost.Commits, c.SHA)
+		}
+	}
+
+	// A pull request is reviewed once per push; the first review is when
+	// it was opened
+	opened := make(map[int]*analytics.PullRequestCost)
+	for _, r := range reviews {
+		pr, ok := opened[r.PRNumber]
+		if !ok {
+			pr = &analytics.PullRequestCost{Number: r.PRNumber, URL: r.PRURL, OpenedAt: r.StartedAt}
+			opened[r.PRNumber] = pr
+		}
+		if r.StartedAt.Before(pr.OpenedAt) {
+			pr.OpenedAt = r.StartedAt
+		}
+	}
+	for _, pr := range opened {
+		pr.CostUSD, pr.Tokens = analytics.CostUntil(logs, cost.BeadID, pr.OpenedAt)
+		cost.PullRequests = append(cost.PullRequests, pr)
+	}
+	sort.Slice(cost.PullRequests, func(i, j int) bool { return cost.PullRequests[i].Number < cost.PullRequests[j].Number })
+}
@@ -0,0 +1,55 @@
+package loom
+
+import (
+	"context"
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/costestimate"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newCostEstimator opens the dispatch cost estimator over db, pricing
+// tokens with the provider registry. Without a database estimates are
+// still made and held to the thresholds, but not recorded or calibrated.
+func newCostEstimator(db *database.Database, registry *provider.Registry, cfg config.CostEstimateConfig) *costestimate.Estimator {
+	ec := costestimate.DefaultConfig()
+	ec.WarnUSD = cfg.WarnUSD
+	ec.BlockUSD = cfg.BlockUSD
+	if cfg.BudgetWarnShare > 0 {
+		ec.BudgetWarnShare = cfg.BudgetWarnShare
+	}
+	var store costestimate.Store
+	if db != nil {
+		store = db
+	}
+	var coster costestimate.Coster
+	if registry != nil {
+		coster = registry
+	}
+	return costestimate.New(store, coster, ec)
+}
+
+// GetCostEstimator returns the dispatch cost estimator
+func (a *Loom) GetCostEstimator() *costestimate.Estimator {
+	return a.costEstimator
+}
+
+// dispatchCosts estimates dispatches against what their organization has
+// left of its daily budget
+type dispatchCosts struct {
+	estimator *costestimate.Estimator
+	gate      *policyGate
+}
+
+func (c *dispatchCosts) EstimateDispatch(ctx context.Context, req costestimate.Request) (*costestimate.Estimate, error) {
+	req.RemainingBudgetUSD = c.gate.orgBudgetRemaining(ctx, req.ProjectID)
+	return c.estimator.Estimate(ctx, req)
+}
+
+func (c *dispatchCosts) CompleteDispatch(ctx context.Context, taskID string, tokens int64, iterations int, costUSD float64) {
+	if err := c.estimator.Complete(ctx, taskID, tokens, iterations, costUSD); err != nil {
+		log.Printf("[CostEstimate] Failed to record the cost of %s: %v", taskID, err)
+	}
+}
@@ -0,0 +1,358 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+const (
+	// defaultKeyRotationGracePeriod is how long a replaced provider key stays
+	// in the key store (and can be rolled back to) after a swap.
+	defaultKeyRotationGracePeriod = 15 * time.Minute
+
+	credentialProbeTimeout = 15 * time.Second
+)
+
+// keyRotationGracePeriod returns the configured grace period before an old
+// provider key is retired
+func (a *Loom) keyRotationGracePeriod() time.Duration {
+	if a.config != nil && a.config.Security.KeyRotationGracePeriod > 0 {
+		return a.config.Security.KeyRotationGracePeriod
+	}
+	return defaultKeyRotationGracePeriod
+}
+
+// RotateProviderCredential stages, verifies and swaps in a new provider API
+// key in one call. The old key is retired after the grace period.
+func (a *Loom) RotateProviderCredential(ctx context.Context, providerID, newKey, initiatedBy string) (*database.KeyRotation, error) {
+	if _, err := a.StageProviderCredential(providerID, newKey, initiatedBy); err != nil {
+		return nil, err
+	}
+	if _, err := a.VerifyProviderCredential(ctx, providerID); err != nil {
+		return nil, err
+	}
+	return a.PromoteProviderCredential(providerID)
+}
+
+// StageProviderCredential stores a new API key for a provider without
+// putting it into service. A pending staged key is superseded.
+func (a *Loom) StageProviderCredential(providerID, newKey, initiatedBy string) (*database.KeyRotation, error) {
+	if newKey == "" {
+		return nil, fmt.Errorf("api key is required")
+	}
+	p, err := a.rotationProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := a.database.GetLatestKeyRotation(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		switch latest.Status {
+		case database.KeyRotationSwapped:
+			return nil, fmt.Errorf("previous rotation %s is still in its grace period; roll back or wait for retirement", latest.ID)
+		case database.KeyRotationStaged, database.KeyRotationVerified:
+			_ = a.keyManager.DeleteKey(latest.NewKeyID)
+			latest.Status = database.KeyRotationCanceled
+			latest.Error = "superseded by a newer staged key"
+			if err := a.database.UpsertKeyRotation(latest); err != nil {
+				return nil, err
+			}
+			auditRotation("provider.credential.canceled", latest, nil)
+		}
+	}
+
+	rotation := &database.KeyRotation{
+		ID:          "rot-" + uuid.New().String()[:8],
+		ProviderID:  providerID,
+		OldKeyID:    p.KeyID,
+		Status:      database.KeyRotationStaged,
+		InitiatedBy: initiatedBy,
+		StagedAt:    time.Now(),
+	}
+	rotation.NewKeyID = fmt.Sprintf("%s-api-key-%s", providerID, rotation.ID)
+
+	desc := fmt.Sprintf("API key for %s (rotation %s)", p.Name, rotation.ID)
+	if err := a.keyManager.StoreKey(rotation.NewKeyID, p.Name, desc, newKey); err != nil {
+		return nil, fmt.Errorf("failed to store staged key: %w", err)
+	}
+	if err := a.database.UpsertKeyRotation(rotation); err != nil {
+		_ = a.keyManager.DeleteKey(rotation.NewKeyID)
+		return nil, err
+	}
+
+	auditRotation("provider.credential.staged", rotation, nil)
+	return rotation, nil
+}
+
+// VerifyProviderCredential probes the provider with the staged key. A key
+// that fails the probe is discarded and the rotation marked failed.
+func (a *Loom) VerifyProviderCredential(ctx context.Context, providerID string) (*database.KeyRotation, error) {
+	p, err := a.rotationProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := a.latestRotationInStatus(providerID, database.KeyRotationStaged)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := a.keyManager.GetKey(rotation.NewKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load staged key: %w", err)
+	}
+
+	if probeErr := probeProviderCredential(ctx, p, key); probeErr != nil {
+		_ = a.keyManager.DeleteKey(rotation.NewKeyID)
+		rotation.Status = database.KeyRotationFailed
+		rotation.Error = probeErr.Error()
+		if err := a.database.UpsertKeyRotation(rotation); err != nil {
+			return nil, err
+		}
+		auditRotation("provider.credential.verify_failed", rotation, probeErr)
+		return rotation, fmt.Errorf("staged key failed health probe: %w", probeErr)
+	}
+
+	now := time.Now()
+	rotation.Status = database.KeyRotationVerified
+	rotation.VerifiedAt = &now
+	if err := a.database.UpsertKeyRotation(rotation); err != nil {
+		return nil, err
+	}
+	auditRotation("provider.credential.verified", rotation, nil)
+	return rotation, nil
+}
+
+// PromoteProviderCredential atomically swaps the verified key into service.
+// In-flight requests finish on the protocol they started with; new requests
+// pick up the new key. The old key is kept until the grace period elapses.
+func (a *Loom) PromoteProviderCredential(providerID string) (*database.KeyRotation, error) {
+	p, err := a.rotationProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := a.latestRotationInStatus(providerID, database.KeyRotationVerified)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := a.keyManager.GetKey(rotation.NewKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load verified key: %w", err)
+	}
+
+	if err := a.swapProviderKey(p, rotation.NewKeyID, key); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	retireAt := now.Add(a.keyRotationGracePeriod())
+	rotation.Status = database.KeyRotationSwapped
+	rotation.SwappedAt = &now
+	rotation.RetireAt = &retireAt
+	if err := a.database.UpsertKeyRotation(rotation); err != nil {
+		return nil, err
+	}
+	auditRotation("provider.credential.swapped", rotation, nil)
+	return rotation, nil
+}
+
+// RollbackProviderCredential restores the previous key for a provider whose
+// rotation is still within its grace period.
+func (a *Loom) RollbackProviderCredential(providerID string) (*database.KeyRotation, error) {
+	p, err := a.rotationProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	rotation, err := a.latestRotationInStatus(providerID, database.KeyRotationSwapped)
+	if err != nil {
+		return nil, err
+	}
+
+	oldKey := ""
+	if rotation.OldKeyID != "" {
+		oldKey, err = a.keyManager.GetKey(rotation.OldKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load previous key: %w", err)
+		}
+	}
+
+	if err := a.swapProviderKey(p, rotation.OldKeyID, oldKey); err != nil {
+		return nil, err
+	}
+	_ = a.keyManager.DeleteKey(rotation.NewKeyID)
+
+	rotation.Status = database.KeyRotationRolledBack
+	rotation.RetireAt = nil
+	if err := a.database.UpsertKeyRotation(rotation); err != nil {
+		return nil, err
+	}
+	auditRotation("provider.credential.rolled_back", rotation, nil)
+	return rotation, nil
+}
+
+// RetireExpiredCredentials deletes replaced provider keys whose grace period
+// has elapsed. It is called from the maintenance loop.
+func (a *Loom) RetireExpiredCredentials() int {
+	if a.database == nil || a.keyManager == nil || !a.keyManager.IsUnlocked() {
+		return 0
+	}
+
+	due, err := a.database.ListKeyRotationsDueForRetirement(time.Now())
+	if err != nil {
+		log.Printf("[KeyRotation] Failed to list rotations due for retirement: %v", err)
+		return 0
+	}
+
+	retired := 0
+	for _, rotation := range due {
+		if rotation.OldKeyID != "" && rotation.OldKeyID != rotation.NewKeyID {
+			if err := a.keyManager.DeleteKey(rotation.OldKeyID); err != nil {
+				auditRotation("provider.credential.retire_failed", rotation, err)
+				continue
+			}
+		}
+		now := time.Now()
+		rotation.Status = database.KeyRotationRetired
+		rotation.RetiredAt = &now
+		if err := a.database.UpsertKeyRotation(rotation); err != nil {
+			log.Printf("[KeyRotation] Failed to record retirement of %s: %v", rotation.ID, err)
+			continue
+		}
+		auditRotation("provider.credential.retired", rotation, nil)
+		retired++
+	}
+	return retired
+}
+
+// ListProviderCredentialRotations returns the rotation history for a provider
+func (a *Loom) ListProviderCredentialRotations(providerID string, limit int) ([]*database.KeyRotation, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	return a.database.ListKeyRotations(providerID, limit)
+}
+
+// rotationProvider loads a provider and checks that rotation is possible
+func (a *Loom) rotationProvider(providerID string) (*internalmodels.Provider, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	if a.keyManager == nil || !a.keyManager.IsUnlocked() {
+		return nil, fmt.Errorf("key manager is not available or locked")
+	}
+	p, err := a.database.GetProvider(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("provider %s not found", providerID)
+	}
+	return p, nil
+}
+
+func (a *Loom) latestRotationInStatus(providerID, status string) (*database.KeyRotation, error) {
+	rotation, err := a.database.GetLatestKeyRotation(providerID)
+	if err != nil {
+		return nil, err
+	}
+	if rotation == nil || rotation.Status != status {
+		return nil, fmt.Errorf("provider %s has no %s credential rotation", providerID, status)
+	}
+	return rotation, nil
+}
+
+// swapProviderKey points the provider at keyID and replaces the registry
+// protocol with one that authenticates using key
+func (a *Loom) swapProviderKey(p *internalmodels.Provider, keyID, key string) error {
+	p.KeyID = keyID
+	p.RequiresKey = keyID != ""
+	if err := a.database.UpsertProvider(p); err != nil {
+		return err
+	}
+
+	cfg := rotationProviderConfig(p)
+	if registered, err := a.providerRegistry.Get(p.ID); err == nil && registered.Config != nil {
+		copied := *registered.Config
+		cfg = &copied
+	}
+	cfg.APIKey = key
+	if err := a.providerRegistry.Upsert(cfg); err != nil {
+		return fmt.Errorf("failed to swap provider key: %w", err)
+	}
+	return nil
+}
+
+// probeProviderCredential lists models with the candidate key
+func probeProviderCredential(ctx context.Context, p *internalmodels.Provider, key string) error {
+	cfg := rotationProviderConfig(p)
+	cfg.APIKey = key
+	protocol, err := provider.NewProtocol(cfg)
+	if err != nil {
+		return err
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, credentialProbeTimeout)
+	defer cancel()
+	models, err := protocol.GetModels(probeCtx)
+	if err != nil {
+		return err
+	}
+	if len(models) == 0 {
+		return fmt.Errorf("provider returned no models")
+	}
+	return nil
+}
+
+func rotationProviderConfig(p *internalmodels.Provider) *provider.ProviderConfig {
+	endpoint := p.Endpoint
+	if !provider.HasNativeEndpoint(p.Type) {
+		endpoint = normalizeProviderEndpoint(endpoint)
+	}
+	return &provider.ProviderConfig{
+		ID:                     p.ID,
+		Name:                   p.Name,
+		Type:                   p.Type,
+		Endpoint:               endpoint,
+		Model:                  p.SelectedModel,
+		ConfiguredModel:        p.ConfiguredModel,
+		SelectedModel:          p.SelectedModel,
+		SelectedGPU:            p.SelectedGPU,
+		Status:                 p.Status,
+		LastHeartbeatAt:        p.LastHeartbeatAt,
+		LastHeartbeatLatencyMs: p.LastHeartbeatLatencyMs,
+		Tags:                   p.Tags,
+		OrgID:                  p.OrgID,
+	}
+}
+
+// auditRotation writes a rotation step to the security audit log. Key
+// material is never logged; only key ids.
+func auditRotation(event string, rotation *database.KeyRotation, err error) {
+	fields := map[string]interface{}{
+		"rotation_id":  rotation.ID,
+		"provider_id":  rotation.ProviderID,
+		"old_key_id":   rotation.OldKeyID,
+		"new_key_id":   rotation.NewKeyID,
+		"status":       rotation.Status,
+		"initiated_by": rotation.InitiatedBy,
+	}
+	if rotation.RetireAt != nil {
+		fields["retire_at"] = rotation.RetireAt.UTC().Format(time.RFC3339)
+	}
+	if err != nil {
+		fields["error"] = err.Error()
+	}
+	observability.SecurityAudit(event, fields)
+}
@@ -0,0 +1,142 @@
+package loom
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/keymanager"
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+// setupRotationTest registers an OpenAI-compatible provider backed by a test
+// server that only accepts the given keys
+func setupRotationTest(t *testing.T, validKeys ...string) *Loom {
+	t.Helper()
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, k := range validKeys {
+			if r.Header.Get("Authorization") == "Bearer "+k {
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(`{"data":[{"id":"test-model"}]}`))
+				return
+			}
+		}
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	t.Cleanup(srv.Close)
+
+	km := keymanager.NewKeyManager(filepath.Join(t.TempDir(), "keys.json"))
+	if err := km.Unlock("test-password"); err != nil {
+		t.Fatalf("Unlock failed: %v", err)
+	}
+	l.SetKeyManager(km)
+
+	if err := km.StoreKey("p1-api-key", "p1", "API key for p1", "old-key"); err != nil {
+		t.Fatalf("StoreKey failed: %v", err)
+	}
+	p := &internalmodels.Provider{
+		ID:          "p1",
+		Name:        "p1",
+		Type:        "openai",
+		Endpoint:    srv.URL,
+		RequiresKey: true,
+		KeyID:       "p1-api-key",
+		Status:      "active",
+	}
+	if err := l.GetDatabase().UpsertProvider(p); err != nil {
+		t.Fatalf("UpsertProvider failed: %v", err)
+	}
+	return l
+}
+
+func TestRotateProviderCredential(t *testing.T) {
+	l := setupRotationTest(t, "old-key", "new-key")
+	l.config.Security.KeyRotationGracePeriod = time.Millisecond
+
+	rotation, err := l.RotateProviderCredential(context.Background(), "p1", "new-key", "user-admin")
+	if err != nil {
+		t.Fatalf("RotateProviderCredential failed: %v", err)
+	}
+	if rotation.Status != database.KeyRotationSwapped || rotation.OldKeyID != "p1-api-key" {
+		t.Errorf("unexpected rotation: %+v", rotation)
+	}
+
+	p, _ := l.GetDatabase().GetProvider("p1")
+	if p.KeyID != rotation.NewKeyID {
+		t.Errorf("provider key id = %q, want %q", p.KeyID, rotation.NewKeyID)
+	}
+	registered, err := l.GetProviderRegistry().Get("p1")
+	if err != nil || registered.Config.APIKey != "new-key" {
+		t.Errorf("registry not using new key: %+v, %v", registered, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	if n := l.RetireExpiredCredentials(); n != 1 {
+		t.Fatalf("RetireExpiredCredentials = %d, want 1", n)
+	}
+	if _, err := l.GetKeyManager().GetKey("p1-api-key"); err == nil {
+		t.Error("expected old key to be deleted after retirement")
+	}
+	latest, _ := l.GetDatabase().GetLatestKeyRotation("p1")
+	if latest.Status != database.KeyRotationRetired || latest.RetiredAt == nil {
+		t.Errorf("expected retired rotation, got %+v", latest)
+	}
+}
+
+func TestVerifyProviderCredential_FailedProbe(t *testing.T) {
+	l := setupRotationTest(t, "old-key")
+
+	staged, err := l.StageProviderCredential("p1", "bad-key", "user-admin")
+	if err != nil {
+		t.Fatalf("StageProviderCredential failed: %v", err)
+	}
+	if _, err := l.VerifyProviderCredential(context.Background(), "p1"); err == nil {
+		t.Fatal("expected verification to fail")
+	}
+	if _, err := l.GetKeyManager().GetKey(staged.NewKeyID); err == nil {
+		t.Error("expected failed staged key to be discarded")
+	}
+	if _, err := l.PromoteProviderCredential("p1"); err == nil {
+		t.Error("expected promote to refuse an unverified key")
+	}
+
+	p, _ := l.GetDatabase().GetProvider("p1")
+	if p.KeyID != "p1-api-key" {
+		t.Errorf("provider key changed after failed rotation: %q", p.KeyID)
+	}
+}
+
+func TestRollbackProviderCredential(t *testing.T) {
+	l := setupRotationTest(t, "old-key", "new-key")
+
+	rotation, err := l.RotateProviderCredential(context.Background(), "p1", "new-key", "user-admin")
+	if err != nil {
+		t.Fatalf("RotateProviderCredential failed: %v", err)
+	}
+	if _, err := l.StageProviderCredential("p1", "another-key", "user-admin"); err == nil {
+		t.Error("expected staging to be refused during the grace period")
+	}
+
+	if _, err := l.RollbackProviderCredential("p1"); err != nil {
+		t.Fatalf("RollbackProviderCredential failed: %v", err)
+	}
+	p, _ := l.GetDatabase().GetProvider("p1")
+	if p.KeyID != "p1-api-key" {
+		t.Errorf("provider key id = %q after rollback", p.KeyID)
+	}
+	registered, _ := l.GetProviderRegistry().Get("p1")
+	if registered.Config.APIKey != "old-key" {
+		t.Error("registry not restored to old key")
+	}
+	if _, err := l.GetKeyManager().GetKey(rotation.NewKeyID); err == nil {
+		t.Error("expected rolled back key to be deleted")
+	}
+}
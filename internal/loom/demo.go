@@ -0,0 +1,83 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/demo"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// GetDemoManager returns the demo mode manager
+func (a *Loom) GetDemoManager() *demo.Manager {
+	return a.demo
+}
+
+// ProvisionDemo creates a demo project on a synthetic repository with seeded
+// bugs. Only the scripted demo provider may work it, and it uses the
+// branch-pr strategy so every fix arrives as a pull request for a review bead
+// to approve. The seeded beads are chained so that one bead at a time has the
+// shared working copy.
+func (a *Loom) ProvisionDemo(ctx context.Context) (*demo.Project, error) {
+	if a.demo == nil {
+		return nil, fmt.Errorf("demo mode not available")
+	}
+	a.ensureDemoProvider()
+
+	p, err := a.CreateProject("Demo", "", "main", ".beads", map[string]string{
+		"demo":          "true",
+		"build_command": "go build ./...",
+		"test_command":  "go test ./...",
+	})
+	if err != nil {
+		return nil, err
+	}
+	workDir := a.gitopsManager.GetProjectWorkDir(p.ID)
+	origin := workDir + ".git"
+	if err := a.projectManager.UpdateProject(p.ID, map[string]interface{}{
+		"git_repo":     origin,
+		"git_strategy": string(models.GitStrategyBranch),
+		"compliance":   &models.ComplianceConstraints{RequiredProviderTags: []string{provider.DemoTag}},
+	}); err != nil {
+		return nil, err
+	}
+	p.GitAuthMethod = models.GitAuthNone
+
+	// The pre-push gate wants a project key even for a local remote
+	if _, err := a.gitopsManager.EnsureProjectSSHKey(p.ID); err != nil {
+		_ = a.DeleteProject(p.ID)
+		return nil, err
+	}
+	dp, err := a.demo.Provision(ctx, p.ID, workDir, origin)
+	if err != nil {
+		_ = a.DeleteProject(p.ID)
+		return nil, err
+	}
+	for i := 1; i < len(dp.BeadIDs); i++ {
+		if err := a.beadsManager.AddDependency(dp.BeadIDs[i], dp.BeadIDs[i-1], "blocks"); err != nil {
+			return nil, err
+		}
+	}
+	if a.database != nil {
+		_ = a.database.UpsertProject(p)
+	}
+	return dp, nil
+}
+
+// ensureDemoProvider registers the scripted mock that works demo beads. It
+// exists only in memory and carries the demo tag, so dispatch never routes a
+// real project to it.
+func (a *Loom) ensureDemoProvider() {
+	if _, err := a.providerRegistry.Get(demo.ProviderID); err == nil {
+		return
+	}
+	a.providerRegistry.UpsertProtocol(&provider.ProviderConfig{
+		ID:     demo.ProviderID,
+		Name:   "Demo (scripted mock)",
+		Type:   "mock",
+		Model:  "mock-model",
+		Status: "active",
+		Tags:   []string{provider.DemoTag},
+	}, provider.NewScriptedMockProvider(a.demo.Respond))
+}
@@ -0,0 +1,218 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/depupdates"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/onboarding"
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// dependencyUpdatesKey set to "false" in a project's context opts the
+	// project out of the dependency update workflow
+	dependencyUpdatesKey = "dependency_updates"
+	// dependencyGroupKey and dependencyFingerprintKey tie a bead to the
+	// update group it was filed for and the updates it lists
+	dependencyGroupKey       = "dependency_group"
+	dependencyFingerprintKey = "dependency_fingerprint"
+	// prNotesKey holds what the pull requests of a bead add to their body
+	prNotesKey = "pr_notes"
+
+	defaultDependencyUpdateInterval = 24 * time.Hour
+)
+
+// CheckDependencyUpdates checks a project's working copy for outdated Go
+// modules and npm packages and files a bead per ecosystem and semver risk,
+// pinned to the configured model tier, whose pull request carries the
+// changelogs of the new releases. A group's open bead is refreshed rather
+// than filed again. With dryRun the groups are only reported.
+func (a *Loom) CheckDependencyUpdates(ctx context.Context, projectID, actor string, dryRun bool) (*depupdates.Report, error) {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+	if p.WorkDir == "" {
+		return nil, fmt.Errorf("project %s has no working copy to check; clone it first", projectID)
+	}
+
+	checker := &depupdates.Checker{
+		Deps:       onboarding.CommandDependencyChecker{},
+		Changelogs: &depupdates.GitHubReleases{Token: a.config.DependencyUpdates.GitHubToken},
+	}
+	groups, warnings, err := checker.Check(ctx, p.WorkDir)
+	if err != nil {
+		return nil, err
+	}
+	report := &depupdates.Report{ProjectID: projectID, CheckedAt: time.Now().UTC(), Warnings: warnings}
+	for _, g := range groups {
+		report.Groups = append(report.Groups, depupdates.FiledGroup{Group: g, Key: g.Key()})
+	}
+	if dryRun {
+		return report, nil
+	}
+
+	open := a.openDependencyBeads(projectID)
+	for i := range report.Groups {
+		fg := &report.Groups[i]
+		if err := a.fileDependencyGroup(fg, open[fg.Key], projectID); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to file bead for %s: %v", fg.Key, err))
+		}
+	}
+	report.Filed = true
+
+	outcomes := make(map[string]string, len(report.Groups))
+	for _, fg := range report.Groups {
+		outcomes[fg.Key] = fg.Outcome
+	}
+	audit.Record(audit.Event{
+		Actor:     actor,
+		Action:    "project.dependency_updates",
+		Resource:  projectID,
+		ProjectID: projectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"groups":   outcomes,
+			"warnings": report.Warnings,
+		},
+	})
+	return report, nil
+}
+
+// openDependencyBeads returns the project's beads filed for update groups
+// and not closed yet, by group
+func (a *Loom) openDependencyBeads(projectID string) map[string]*models.Bead {
+	beads, err := a.beadsManager.ListBeads(map[string]interface{}{"project_id": projectID})
+	if err != nil {
+		return nil
+	}
+	open := make(map[string]*models.Bead)
+	for _, b := range beads {
+		if b.Status == models.BeadStatusClosed || b.Context[dependencyGroupKey] == "" {
+			continue
+		}
+		open[b.Context[dependencyGroupKey]] = b
+	}
+	return open
+}
+
+// fileDependencyGroup files the bead of an update group, or refreshes the
+// group's open bead when its updates changed and no agent is working it
+func (a *Loom) fileDependencyGroup(fg *depupdates.FiledGroup, existing *models.Bead, projectID string) error {
+	fingerprint := fg.Fingerprint()
+	if existing != nil {
+		fg.BeadID = existing.ID
+		switch {
+		case existing.Context[dependencyFingerprintKey] == fingerprint:
+			fg.Outcome = depupdates.BeadUnchanged
+		case existing.Status == models.BeadStatusInProgress:
+			fg.Outcome = depupdates.BeadBusy
+		default:
+			if _, err := a.UpdateBead(existing.ID, map[string]interface{}{
+				"title":       fg.Title(),
+				"description": fg.Description(),
+				"priority":    fg.Priority(),
+				"context": map[string]string{
+					dependencyFingerprintKey: fingerprint,
+					prNotesKey:               fg.PullRequestNotes(),
+				},
+			}); err != nil {
+				return err
+			}
+			fg.Outcome = depupdates.BeadUpdated
+		}
+		return nil
+	}
+
+	tier := a.config.DependencyUpdates.ModelTier
+	if tier == "" {
+		tier = persona.TierSmall
+	}
+	bead, err := a.CreateBead(fg.Title(), fg.Description(), fg.Priority(), "task", projectID)
+	if err != nil {
+		return err
+	}
+	fg.BeadID = bead.ID
+	fg.Outcome = depupdates.BeadCreated
+	if _, err := a.UpdateBead(bead.ID, map[string]interface{}{
+		"tags": []string{"dependencies", fg.Ecosystem, fg.Risk},
+		"context": map[string]string{
+			dependencyGroupKey:        fg.Key,
+			dependencyFingerprintKey:  fingerprint,
+			dispatch.BeadModelTierKey: tier,
+			prNotesKey:                fg.PullRequestNotes(),
+		},
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
+// PullRequestNotes satisfies actions.PullRequestNoter: the pull requests of
+// a dependency update bead carry the changelogs of its updates, and those
+// of a bead that ran static analysis its latest findings
+func (a *Loom) PullRequestNotes(ctx context.Context, actx actions.ActionContext) string {
+	if actx.BeadID == "" {
+		return ""
+	}
+	bead, err := a.beadsManager.GetBead(actx.BeadID)
+	if err != nil || bead.Context == nil {
+		return ""
+	}
+	notes := bead.Context[prNotesKey]
+	if report, err := a.StaticAnalysisReport(actx.BeadID); err == nil && report != nil {
+		if notes != "" {
+			notes = strings.TrimRight(notes, "\n") + "\n\n"
+		}
+		notes += report.Markdown(prStaticAnalysisFindings)
+	}
+	return notes
+}
+
+// runDependencyUpdates checks every cloned project that has not opted out
+// for dependency updates once per interval until ctx is done
+func (a *Loom) runDependencyUpdates(ctx context.Context) {
+	cfg := a.config.DependencyUpdates
+	if !cfg.Enabled {
+		return
+	}
+	if err := persona.ValidateModelTier(cfg.ModelTier); err != nil {
+		log.Printf("[DependencyUpdates] Not checking projects: %v", err)
+		return
+	}
+	interval := cfg.Interval
+	if interval <= 0 {
+		interval = defaultDependencyUpdateInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, p := range a.projectManager.ListProjects() {
+				if ctx.Err() != nil {
+					return
+				}
+				if p.WorkDir == "" || p.Context[dependencyUpdatesKey] == "false" {
+					continue
+				}
+				report, err := a.CheckDependencyUpdates(ctx, p.ID, "system", false)
+				if err != nil {
+					log.Printf("[DependencyUpdates] Check of project %s failed: %v", p.ID, err)
+					continue
+				}
+				log.Printf("[DependencyUpdates] Project %s has %d update groups", p.ID, len(report.Groups))
+			}
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/depupdates"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/onboarding"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestFileDependencyGroup(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	proj, err := l.CreateProject("deps", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	groups := depupdates.GroupUpdates([]onboarding.Dependency{
+		{Ecosystem: "go", Name: "github.com/o/lib", Current: "v1.2.0", Latest: "v1.3.0"},
+	})
+	file := func() *depupdates.FiledGroup {
+		t.Helper()
+		fg := &depupdates.FiledGroup{Group: groups[0], Key: groups[0].Key()}
+		if err := l.fileDependencyGroup(fg, l.openDependencyBeads(proj.ID)[fg.Key], proj.ID); err != nil {
+			t.Fatalf("fileDependencyGroup failed: %v", err)
+		}
+		return fg
+	}
+
+	created := file()
+	if created.Outcome != depupdates.BeadCreated {
+		t.Fatalf("outcome = %s, want created", created.Outcome)
+	}
+	bead, err := l.beadsManager.GetBead(created.BeadID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bead.Context[dispatch.BeadModelTierKey] != "small" || bead.Context[dependencyGroupKey] != "go-minor" || len(bead.Tags) != 3 {
+		t.Errorf("filed bead = %+v", bead)
+	}
+	if notes := l.PullRequestNotes(context.Background(), actions.ActionContext{BeadID: bead.ID}); notes != groups[0].PullRequestNotes() {
+		t.Errorf("pull request notes = %q", notes)
+	}
+
+	if fg := file(); fg.Outcome != depupdates.BeadUnchanged || fg.BeadID != bead.ID {
+		t.Errorf("refiling the same group = %+v, want its bead unchanged", fg)
+	}
+
+	groups[0].Updates[0].Latest = "v1.4.0"
+	if fg := file(); fg.Outcome != depupdates.BeadUpdated || bead.Title != "Update github.com/o/lib from v1.2.0 to v1.4.0 (minor)" {
+		t.Errorf("a newer release = %s, bead titled %q", fg.Outcome, bead.Title)
+	}
+
+	if _, err := l.UpdateBead(bead.ID, map[string]interface{}{"status": models.BeadStatusInProgress}); err != nil {
+		t.Fatal(err)
+	}
+	groups[0].Updates[0].Latest = "v1.5.0"
+	if fg := file(); fg.Outcome != depupdates.BeadBusy {
+		t.Errorf("outcome = %s, want a bead being worked left alone", fg.Outcome)
+	}
+
+	if _, err := l.UpdateBead(bead.ID, map[string]interface{}{"status": models.BeadStatusClosed}); err != nil {
+		t.Fatal(err)
+	}
+	if fg := file(); fg.Outcome != depupdates.BeadCreated || fg.BeadID == bead.ID {
+		t.Errorf("after the bead closed = %+v, want a new bead", fg)
+	}
+}
@@ -0,0 +1,75 @@
+package loom
+
+import (
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/memory"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// defaultEmbeddingCacheTTL is how long embeddings stay cached in the
+// database unless configured otherwise
+const defaultEmbeddingCacheTTL = 30 * 24 * time.Hour
+
+// newReembedder returns the job moving stored lessons to the configured
+// embedder. A provider model falls back to the hash embedder when its
+// endpoint fails; lessons embedded that way are re-embedded later. Model
+// embeddings go through cache, which may be nil. Without a database there
+// are no stored lessons.
+func newReembedder(db *database.Database, cfg config.EmbeddingConfig, cache *memory.EmbeddingCache) *memory.Reembedder {
+	if db == nil {
+		return nil
+	}
+	var current memory.Embedder = memory.NewHashEmbedder()
+	var legacy []memory.Embedder
+	if cfg.Model != "" && cfg.Endpoint != "" {
+		current = memory.NewFallbackEmbedder(cache.Wrap(memory.NewProviderEmbedder(cfg.Endpoint, cfg.APIKey, cfg.Model)))
+	}
+	if cfg.PreviousModel != "" && cfg.PreviousModel != cfg.Model && cfg.Endpoint != "" {
+		legacy = append(legacy, cache.Wrap(memory.NewProviderEmbedder(cfg.Endpoint, cfg.APIKey, cfg.PreviousModel)))
+	}
+	r := memory.NewReembedder(db, current, legacy, memory.ReembedConfig{
+		Rate:      cfg.ReembedRate,
+		BatchSize: cfg.ReembedBatch,
+		Interval:  cfg.ReembedInterval,
+	})
+	ttl := cfg.CacheTTL
+	if ttl <= 0 {
+		ttl = defaultEmbeddingCacheTTL
+	}
+	r.SetCache(cache, ttl)
+	return r
+}
+
+// GetReembedder returns the lesson re-embedding job
+func (a *Loom) GetReembedder() *memory.Reembedder {
+	return a.reembedder
+}
+
+// GetEmbeddingCache returns the cache of model embeddings
+func (a *Loom) GetEmbeddingCache() *memory.EmbeddingCache {
+	return a.embeddingCache
+}
+
+// lessonSearchConfig converts the lesson search settings for the database
+func lessonSearchConfig(cfg config.LessonSearchConfig) database.LessonSearchConfig {
+	return database.LessonSearchConfig{
+		PageSize:      cfg.PageSize,
+		MaxCandidates: cfg.MaxCandidates,
+		ScanRate:      cfg.ScanRate,
+		MaxScans:      cfg.MaxScans,
+	}
+}
+
+// newLessonIndex returns the HNSW index large projects' lessons are
+// searched through, or nil when it is not enabled
+func newLessonIndex(db *database.Database, cfg config.LessonIndexConfig) *memory.LessonIndex {
+	if db == nil || cfg.MinLessons <= 0 {
+		return nil
+	}
+	return memory.NewLessonIndex(db, memory.LessonIndexConfig{
+		MinLessons:      cfg.MinLessons,
+		RefreshInterval: cfg.RefreshInterval,
+	})
+}
@@ -0,0 +1,131 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/evaluation"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/worker"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// newEvaluation opens the judges over db. Every stored evaluation refreshes
+// the quality the provider scorer ranks by, and the quality of earlier
+// evaluations is loaded now. Without a database there is nowhere to keep
+// evaluations.
+func newEvaluation(a *Loom, db *database.Database, cfg config.EvaluationConfig) *evaluation.Manager {
+	if db == nil || a.providerRegistry == nil {
+		return nil
+	}
+	mgr := evaluation.NewManager(db, &evaluationHost{loom: a}, evaluation.Config{
+		Enabled:      cfg.Enabled,
+		Persona:      cfg.Persona,
+		ProviderID:   cfg.ProviderID,
+		MaxDiffBytes: cfg.MaxDiffBytes,
+		Timeout:      cfg.Timeout,
+		Window:       cfg.Window,
+		MinSamples:   cfg.MinSamples,
+	})
+	mgr.OnEvaluated(func(e *evaluation.Evaluation) {
+		if e.Error == "" {
+			refreshProviderQuality(mgr, a.providerRegistry)
+		}
+	})
+	refreshProviderQuality(mgr, a.providerRegistry)
+	return mgr
+}
+
+// refreshProviderQuality hands the scorer the quality of every provider
+// with enough evaluations in the window
+func refreshProviderQuality(mgr *evaluation.Manager, registry *provider.Registry) {
+	quality, err := mgr.Quality()
+	if err != nil {
+		log.Printf("[Evaluation] Failed to load provider quality: %v", err)
+		return
+	}
+	for _, q := range quality {
+		if q.Evaluations >= mgr.Config().MinSamples {
+			registry.UpdateProviderQuality(q.ProviderID, q.Quality, q.Evaluations)
+		}
+	}
+}
+
+// GetEvaluation returns the judges that grade agent output
+func (a *Loom) GetEvaluation() *evaluation.Manager {
+	return a.evaluation
+}
+
+// JudgeDispatch satisfies dispatch.OutputJudge: the project's judges grade
+// the dispatch's answer and diff in the background when evaluation is
+// enabled
+func (a *Loom) JudgeDispatch(b *models.Bead, dispatchID, agentID, providerID string, result *worker.TaskResult, diff string) {
+	if a.evaluation == nil || b == nil || result == nil {
+		return
+	}
+	model := ""
+	if p, err := a.providerRegistry.Get(providerID); err == nil && p != nil && p.Config != nil {
+		model = p.Config.Model
+	}
+	a.evaluation.Submit(evaluation.Output{
+		ProjectID:    b.ProjectID,
+		BeadID:       b.ID,
+		DispatchID:   dispatchID,
+		AgentID:      agentID,
+		ProviderID:   providerID,
+		Model:        model,
+		Instructions: strings.TrimSpace(b.Title + "\n\n" + b.Description),
+		Response:     result.Response,
+		Diff:         diff,
+	})
+}
+
+// evaluationHost grades on the registered providers
+type evaluationHost struct {
+	loom *Loom
+}
+
+// Complete sends the grading prompt with the persona's instructions as the
+// system prompt, to providerID or to the best active provider for the
+// persona's model tier that the project allows. The provider whose work is
+// graded does not grade it when another can, and the demo's scripted
+// provider cannot grade, so it is only used when configured.
+func (h *evaluationHost) Complete(ctx context.Context, projectID, personaName, providerID, avoid, prompt string) (string, string, string, error) {
+	a := h.loom
+	p, err := a.personaManager.LoadPersona(dispatch.QualifyPersonaName(personaName))
+	if err != nil {
+		return "", "", "", fmt.Errorf("unknown judge persona %s: %w", personaName, err)
+	}
+	if providerID == "" {
+		var fallback string
+		candidates := a.providerRegistry.ListActiveForComplexity(dispatch.TierComplexity(p.ModelTier))
+		for _, c := range candidates {
+			if c == nil || c.Config == nil || slices.Contains(c.Config.Tags, provider.DemoTag) || !a.providerServesProject(c.Config, projectID) {
+				continue
+			}
+			if c.Config.ID != avoid {
+				providerID = c.Config.ID
+				break
+			}
+			fallback = c.Config.ID
+		}
+		if providerID == "" {
+			providerID = fallback
+		}
+		if providerID == "" {
+			return "", "", "", fmt.Errorf("no active provider to judge with")
+		}
+	}
+	systemPrompt := p.Instructions
+	if systemPrompt == "" {
+		systemPrompt = p.Character
+	}
+	response, model, err := (&goldenCompleter{registry: a.providerRegistry}).Complete(ctx, providerID, systemPrompt, prompt)
+	return response, providerID, model, err
+}
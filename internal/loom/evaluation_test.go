@@ -0,0 +1,42 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/evaluation"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestEvaluationHost_JudgesOnAnotherProvider(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	if l.GetEvaluation() == nil {
+		t.Fatal("expected evaluation to be configured")
+	}
+
+	answer := `{"summary": "ok", "scores": [{"criterion": "instructions", "score": 9}, {"criterion": "correctness", "score": 9}, {"criterion": "diff_quality", "score": 9}]}`
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "worker", Type: "mock", Status: "active", Model: "m"}, &reviewProtocol{answer: answer})
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "judge", Type: "mock", Status: "active", Model: "m"}, &reviewProtocol{answer: answer})
+
+	host := &evaluationHost{loom: l}
+	for _, avoid := range []string{"worker", "judge"} {
+		_, providerID, _, err := host.Complete(context.Background(), "proj-1", "code-reviewer", "", avoid, "grade this")
+		if err != nil {
+			t.Fatalf("Complete failed: %v", err)
+		}
+		if providerID == avoid {
+			t.Errorf("expected %s not to judge its own work", avoid)
+		}
+	}
+
+	list, err := l.GetEvaluation().Evaluate(context.Background(), evaluation.Output{ProjectID: "proj-1", ProviderID: "worker", Diff: "+x\n"})
+	if err != nil || len(list) != 1 || list[0].Error != "" {
+		t.Fatalf("Evaluate = %+v, %v", list, err)
+	}
+	// One evaluation is below the default minimum, so ranking is unchanged
+	if score, ok := l.providerRegistry.GetScorer().GetScore("worker"); ok && score.QualitySamples != 0 {
+		t.Errorf("expected quality to wait for enough samples, got %+v", score)
+	}
+}
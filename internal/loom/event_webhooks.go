@@ -0,0 +1,46 @@
+package loom
+
+import (
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+)
+
+// newEventWebhooks starts delivering activity events to subscribed external
+// endpoints. It reads the event bus rather than the activity feed so that
+// events the feed aggregates are still delivered one by one. Without a
+// database there is nothing to subscribe.
+func newEventWebhooks(db *database.Database, eb *eventbus.EventBus, activityMgr *activity.Manager) *eventhooks.Manager {
+	if db == nil || eb == nil || activityMgr == nil {
+		return nil
+	}
+	hooks := eventhooks.NewManager(db, eventhooks.DefaultConfig())
+	if err := hooks.Resume(); err != nil {
+		log.Printf("Warning: failed to resume pending webhook deliveries: %v", err)
+	}
+
+	sub := eb.Subscribe("event-webhooks", func(event *eventbus.Event) bool {
+		return activityMgr.Tracks(string(event.Type))
+	})
+	go func() {
+		for event := range sub.Channel {
+			hooks.Publish(eventhooks.Event{
+				ID:        event.ID,
+				Type:      string(event.Type),
+				Timestamp: event.Timestamp,
+				ProjectID: event.ProjectID,
+				Data:      event.Data,
+			})
+		}
+	}()
+	return hooks
+}
+
+// GetEventWebhooks returns the outbound event webhook manager, or nil
+// without a database
+func (a *Loom) GetEventWebhooks() *eventhooks.Manager {
+	return a.eventWebhooks
+}
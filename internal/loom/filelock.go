@@ -1,48 +1,203 @@
 package loom
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
 // FileLockManager manages file locks to prevent merge conflicts
 type FileLockManager struct {
 	locks   map[string]*models.FileLock // key: projectID:filePath
+	waits   map[string]lockWait         // key: agent waiting for a lock
 	mu      sync.RWMutex
 	timeout time.Duration
 }
 
+// lockWait records that an agent was refused a lock another agent holds
+type lockWait struct {
+	holder string
+	since  time.Time
+}
+
+// LockConflictError is returned for a path locked by another agent
+type LockConflictError struct {
+	Path string
+	Held *models.FileLock
+}
+
+func (e *LockConflictError) Error() string {
+	held := ""
+	if e.Held.Directory && normalizeLockPath(e.Held.FilePath) != normalizeLockPath(e.Path) {
+		held = fmt.Sprintf(" (directory %s)", e.Held.FilePath)
+	}
+	msg := fmt.Sprintf("%s already locked by agent %s%s", e.Path, e.Held.AgentID, held)
+	if e.Held.BeadID != "" {
+		msg += " for bead " + e.Held.BeadID
+	}
+	if !e.Held.ExpiresAt.IsZero() {
+		msg += " until " + e.Held.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return msg
+}
+
+// DeadlockError is returned when waiting for a lock would leave a cycle of
+// agents each waiting for a lock the next one holds. The requester's locks
+// are released so the others can finish.
+type DeadlockError struct {
+	Cycle    []string // Agents in the cycle, starting with the requester
+	Released []*models.FileLock
+}
+
+func (e *DeadlockError) Error() string {
+	return fmt.Sprintf("waiting for the lock would deadlock (%s -> %s)", strings.Join(e.Cycle, " -> "), e.Cycle[0])
+}
+
 // NewFileLockManager creates a new file lock manager
 func NewFileLockManager(timeout time.Duration) *FileLockManager {
 	return &FileLockManager{
 		locks:   make(map[string]*models.FileLock),
+		waits:   make(map[string]lockWait),
 		timeout: timeout,
 	}
 }
 
 // lockKey generates a unique key for a file lock
 func (m *FileLockManager) lockKey(projectID, filePath string) string {
-	return fmt.Sprintf("%s:%s", projectID, filePath)
+	return fmt.Sprintf("%s:%s", projectID, normalizeLockPath(filePath))
+}
+
+// normalizeLockPath cleans a path so spellings of one file share a lock,
+// keeping the trailing slash of a directory
+func normalizeLockPath(filePath string) string {
+	cleaned := path.Clean("/" + filePath)
+	if isDirPath(filePath) && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+func isDirPath(filePath string) bool {
+	return strings.HasSuffix(filePath, "/")
+}
+
+// coversPath reports whether a lock is on the path or a directory over it
+func coversPath(lock *models.FileLock, filePath string) bool {
+	held, want := normalizeLockPath(lock.FilePath), normalizeLockPath(filePath)
+	return held == want || (lock.Directory && strings.HasPrefix(want, held))
+}
+
+// lockOverlaps reports whether a lock and a path share any file
+func lockOverlaps(lock *models.FileLock, filePath string) bool {
+	if coversPath(lock, filePath) {
+		return true
+	}
+	return isDirPath(filePath) && strings.HasPrefix(normalizeLockPath(lock.FilePath), normalizeLockPath(filePath))
+}
+
+func (m *FileLockManager) lockExpired(lock *models.FileLock, now time.Time) bool {
+	return !lock.ExpiresAt.IsZero() && now.After(lock.ExpiresAt)
+}
+
+// waitCycle follows the agents holder is waiting for, returning the cycle
+// when it leads back to requester. Waits older than the lock timeout, or
+// for an agent that no longer holds a lock, no longer count.
+func (m *FileLockManager) waitCycle(requester, holder string, now time.Time) []string {
+	cycle := []string{requester}
+	seen := map[string]bool{requester: true}
+	for agent := holder; !seen[agent]; {
+		seen[agent] = true
+		cycle = append(cycle, agent)
+		wait, ok := m.waits[agent]
+		if !ok || (m.timeout > 0 && now.Sub(wait.since) > m.timeout) || !m.holdsLocks(wait.holder, now) {
+			return nil
+		}
+		if wait.holder == requester {
+			return cycle
+		}
+		agent = wait.holder
+	}
+	return nil
+}
+
+func (m *FileLockManager) holdsLocks(agentID string, now time.Time) bool {
+	for _, lock := range m.locks {
+		if lock.AgentID == agentID && !m.lockExpired(lock, now) {
+			return true
+		}
+	}
+	return false
 }
 
-// AcquireLock attempts to acquire a lock on a file
+// releaseLocks removes an agent's locks, only those for beadID when it is
+// set, and forgets what the agent was waiting for
+func (m *FileLockManager) releaseLocks(agentID, beadID string) []*models.FileLock {
+	var released []*models.FileLock
+	for key, lock := range m.locks {
+		if lock.AgentID == agentID && (beadID == "" || lock.BeadID == beadID) {
+			released = append(released, lock)
+			delete(m.locks, key)
+		}
+	}
+	delete(m.waits, agentID)
+	return released
+}
+
+// AcquireLock attempts to acquire a lock on a file. A path ending in "/"
+// locks the directory and every file beneath it.
 func (m *FileLockManager) AcquireLock(projectID, filePath, agentID, beadID string) (*models.FileLock, error) {
+	lock, _, err := m.acquire(projectID, filePath, agentID, beadID)
+	return lock, err
+}
+
+// acquire locks a file or directory for an agent, reporting whether the
+// lock is new rather than one the agent already held. An agent re-locking
+// a path it holds extends the lock; a lock past its expiry is stolen. When
+// the path is held by another agent the requester is recorded as waiting
+// for it, and if that holder is itself waiting, directly or through other
+// agents, for the requester, the requester's locks are released to break
+// the deadlock.
+func (m *FileLockManager) acquire(projectID, filePath, agentID, beadID string) (*models.FileLock, bool, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	now := time.Now()
 	key := m.lockKey(projectID, filePath)
 
-	// Check if already locked
-	if lock, exists := m.locks[key]; exists {
-		// Check if lock has expired
-		if lock.ExpiresAt.IsZero() || time.Now().Before(lock.ExpiresAt) {
-			return nil, fmt.Errorf("file already locked by agent %s", lock.AgentID)
+	var expired []string
+	for k, held := range m.locks {
+		if held.ProjectID != projectID || !lockOverlaps(held, filePath) {
+			continue
+		}
+		if m.lockExpired(held, now) {
+			expired = append(expired, k)
+			continue
+		}
+		if held.AgentID != agentID {
+			if cycle := m.waitCycle(agentID, held.AgentID, now); cycle != nil {
+				released := m.releaseLocks(agentID, "")
+				return nil, false, &DeadlockError{Cycle: cycle, Released: released}
+			}
+			m.waits[agentID] = lockWait{holder: held.AgentID, since: now}
+			return nil, false, &LockConflictError{Path: filePath, Held: held}
+		}
+		if coversPath(held, filePath) {
+			// The agent already holds the path or a directory over it
+			held.BeadID = beadID
+			held.ExpiresAt = now.Add(m.timeout)
+			delete(m.waits, agentID)
+			return held, false, nil
 		}
-		// Lock expired, can proceed
-		delete(m.locks, key)
 	}
 
 	// Create new lock
@@ -51,13 +206,21 @@ func (m *FileLockManager) AcquireLock(projectID, filePath, agentID, beadID strin
 		ProjectID: projectID,
 		AgentID:   agentID,
 		BeadID:    beadID,
-		LockedAt:  time.Now(),
-		ExpiresAt: time.Now().Add(m.timeout),
+		Directory: isDirPath(filePath),
+		LockedAt:  now,
+		ExpiresAt: now.Add(m.timeout),
+	}
+	for _, k := range expired {
+		if held := m.locks[k]; held.AgentID != agentID && lock.StolenFrom == "" {
+			lock.StolenFrom = held.AgentID
+		}
+		delete(m.locks, k)
 	}
 
 	m.locks[key] = lock
+	delete(m.waits, agentID)
 
-	return lock, nil
+	return lock, true, nil
 }
 
 // ReleaseLock releases a file lock
@@ -82,24 +245,20 @@ func (m *FileLockManager) ReleaseLock(projectID, filePath, agentID string) error
 	return nil
 }
 
-// IsLocked checks if a file is currently locked
+// IsLocked checks if a file is currently locked, by its own lock or one on
+// a directory above it
 func (m *FileLockManager) IsLocked(projectID, filePath string) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
-	key := m.lockKey(projectID, filePath)
-	lock, exists := m.locks[key]
-
-	if !exists {
-		return false
-	}
-
-	// Check if expired
-	if !lock.ExpiresAt.IsZero() && time.Now().After(lock.ExpiresAt) {
-		return false
+	now := time.Now()
+	for _, lock := range m.locks {
+		if lock.ProjectID == projectID && coversPath(lock, filePath) && !m.lockExpired(lock, now) {
+			return true
+		}
 	}
 
-	return true
+	return false
 }
 
 // GetLock retrieves a file lock
@@ -188,19 +347,18 @@ func (m *FileLockManager) ReleaseAgentLocks(agentID string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	keysToDelete := make([]string, 0)
+	m.releaseLocks(agentID, "")
 
-	for key, lock := range m.locks {
-		if lock.AgentID == agentID {
-			keysToDelete = append(keysToDelete, key)
-		}
-	}
+	return nil
+}
 
-	for _, key := range keysToDelete {
-		delete(m.locks, key)
-	}
+// ReleaseBeadLocks releases the locks an agent holds for a bead, returning
+// them
+func (m *FileLockManager) ReleaseBeadLocks(agentID, beadID string) []*models.FileLock {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	return nil
+	return m.releaseLocks(agentID, beadID)
 }
 
 // CleanExpiredLocks removes expired locks
@@ -244,3 +402,152 @@ func (m *FileLockManager) ExtendLock(projectID, filePath, agentID string, durati
 
 	return nil
 }
+
+// Bead context keys written by file locking
+const (
+	beadFileLocksKey    = "file_locks"     // Comma-separated paths the bead's agent holds
+	beadFileLockWaitKey = "file_lock_wait" // Why the agent's last lock was refused
+)
+
+// LockFiles satisfies actions.FileLocker: an agent locks the files an
+// action changes before it runs. Locks are held until the agent's task
+// ends, so locks taken for one action are kept while it waits for another.
+func (a *Loom) LockFiles(ctx context.Context, actx actions.ActionContext, paths []string) error {
+	if a.fileLockManager == nil || actx.AgentID == "" || actx.ProjectID == "" {
+		return nil
+	}
+	var locked []string
+	stolen := make(map[string]string)
+	var failure error
+	failed := ""
+	for _, p := range paths {
+		lock, fresh, err := a.fileLockManager.acquire(actx.ProjectID, p, actx.AgentID, actx.BeadID)
+		if err != nil {
+			failure, failed = err, p
+			break
+		}
+		if !fresh {
+			continue
+		}
+		locked = append(locked, lock.FilePath)
+		if lock.StolenFrom != "" {
+			stolen[lock.FilePath] = lock.StolenFrom
+		}
+	}
+	a.fileLocksChanged(actx, locked, stolen, failure)
+
+	var deadlock *DeadlockError
+	var conflict *LockConflictError
+	switch {
+	case errors.As(failure, &deadlock):
+		return fmt.Errorf("cannot lock %s: %v. Your locks on %s were released so agent %s can finish; re-read those files before changing them again",
+			failed, deadlock, strings.Join(lockedPaths(deadlock.Released), ", "), deadlock.Cycle[1])
+	case errors.As(failure, &conflict):
+		return fmt.Errorf("cannot change %s: %v; work on other files first or retry later, the lock is released when that agent's task ends", failed, conflict)
+	}
+	return failure
+}
+
+// ReleaseFiles satisfies actions.FileLocker: the locks an agent took for a
+// bead are released when its task ends
+func (a *Loom) ReleaseFiles(ctx context.Context, actx actions.ActionContext) {
+	if a.fileLockManager == nil || actx.AgentID == "" {
+		return
+	}
+	released := a.fileLockManager.ReleaseBeadLocks(actx.AgentID, actx.BeadID)
+	if len(released) == 0 || actx.BeadID == "" {
+		return
+	}
+	bead, err := a.beadsManager.GetBead(actx.BeadID)
+	if err != nil {
+		return
+	}
+	if err := a.beadsManager.UpdateBead(actx.BeadID, map[string]interface{}{
+		"context": map[string]string{beadFileLocksKey: "", beadFileLockWaitKey: ""},
+	}); err != nil {
+		log.Printf("[FileLocks] Failed to clear locks on bead %s: %v", actx.BeadID, err)
+	}
+	if a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadFilesUnlocked, actx.BeadID, actx.ProjectID, map[string]interface{}{
+			"title":    bead.Title,
+			"agent_id": actx.AgentID,
+			"paths":    lockedPaths(released),
+		})
+	}
+}
+
+// fileLocksChanged records the locks an agent holds and what it is waiting
+// for on its bead, and publishes new, stolen and deadlocked locks
+func (a *Loom) fileLocksChanged(actx actions.ActionContext, locked []string, stolen map[string]string, failure error) {
+	if actx.BeadID == "" {
+		return
+	}
+	bead, err := a.beadsManager.GetBead(actx.BeadID)
+	if err != nil {
+		return
+	}
+	var deadlock *DeadlockError
+	var conflict *LockConflictError
+	isDeadlock := errors.As(failure, &deadlock)
+
+	updates := make(map[string]string)
+	if len(locked) > 0 || isDeadlock {
+		held := a.fileLockManager.ListLocksByAgent(actx.AgentID)
+		var paths []string
+		for _, lock := range held {
+			if lock.BeadID == actx.BeadID {
+				paths = append(paths, lock.FilePath)
+			}
+		}
+		sort.Strings(paths)
+		updates[beadFileLocksKey] = strings.Join(paths, ",")
+	}
+	wait := ""
+	if errors.As(failure, &conflict) {
+		wait = conflict.Error()
+	}
+	if wait != bead.Context[beadFileLockWaitKey] {
+		updates[beadFileLockWaitKey] = wait
+	}
+	if len(updates) > 0 {
+		if err := a.beadsManager.UpdateBead(actx.BeadID, map[string]interface{}{"context": updates}); err != nil {
+			log.Printf("[FileLocks] Failed to record locks on bead %s: %v", actx.BeadID, err)
+		}
+	}
+
+	if a.eventBus == nil {
+		return
+	}
+	if len(locked) > 0 {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadFilesLocked, actx.BeadID, actx.ProjectID, map[string]interface{}{
+			"title":    bead.Title,
+			"agent_id": actx.AgentID,
+			"paths":    locked,
+		})
+	}
+	for p, from := range stolen {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadFileLockStolen, actx.BeadID, actx.ProjectID, map[string]interface{}{
+			"title":         bead.Title,
+			"agent_id":      actx.AgentID,
+			"path":          p,
+			"from_agent_id": from,
+		})
+	}
+	if isDeadlock {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadLockDeadlock, actx.BeadID, actx.ProjectID, map[string]interface{}{
+			"title":    bead.Title,
+			"agent_id": actx.AgentID,
+			"cycle":    deadlock.Cycle,
+			"released": lockedPaths(deadlock.Released),
+		})
+	}
+}
+
+func lockedPaths(locks []*models.FileLock) []string {
+	paths := make([]string, len(locks))
+	for i, lock := range locks {
+		paths[i] = lock.FilePath
+	}
+	sort.Strings(paths)
+	return paths
+}
@@ -0,0 +1,138 @@
+package loom
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestFileLockManager_DirectoryLocks(t *testing.T) {
+	flm := NewFileLockManager(5 * time.Minute)
+
+	lock, err := flm.AcquireLock("proj1", "internal/api/", "agent-1", "bead-1")
+	if err != nil || !lock.Directory {
+		t.Fatalf("AcquireLock(dir) = %+v, %v", lock, err)
+	}
+	if !flm.IsLocked("proj1", "internal/api/server.go") || flm.IsLocked("proj1", "internal/apiary.go") {
+		t.Error("a directory lock should cover the files beneath it and nothing else")
+	}
+	if _, err := flm.AcquireLock("proj1", "./internal/api/server.go", "agent-2", "bead-2"); err == nil {
+		t.Error("a file under another agent's directory lock should be refused")
+	}
+	if _, err := flm.AcquireLock("proj1", "internal/", "agent-2", "bead-2"); err == nil {
+		t.Error("a directory over another agent's lock should be refused")
+	}
+
+	// The holder re-locking a file beneath its directory extends the lock
+	again, err := flm.AcquireLock("proj1", "internal/api/server.go", "agent-1", "bead-1")
+	if err != nil || again != lock {
+		t.Errorf("re-lock by the holder = %+v, %v", again, err)
+	}
+}
+
+func TestFileLockManager_StealsExpiredLocks(t *testing.T) {
+	flm := NewFileLockManager(time.Millisecond)
+
+	_, _ = flm.AcquireLock("proj1", "main.go", "agent-1", "bead-1")
+	time.Sleep(5 * time.Millisecond)
+
+	lock, fresh, err := flm.acquire("proj1", "main.go", "agent-2", "bead-2")
+	if err != nil || !fresh {
+		t.Fatalf("acquire of an expired lock = %v, fresh %v", err, fresh)
+	}
+	if lock.StolenFrom != "agent-1" {
+		t.Errorf("StolenFrom = %q, want agent-1", lock.StolenFrom)
+	}
+}
+
+func TestFileLockManager_BreaksDeadlocks(t *testing.T) {
+	flm := NewFileLockManager(5 * time.Minute)
+
+	_, _ = flm.AcquireLock("proj1", "a.go", "agent-1", "bead-1")
+	_, _ = flm.AcquireLock("proj1", "b.go", "agent-2", "bead-2")
+	_, _ = flm.AcquireLock("proj1", "c.go", "agent-3", "bead-3")
+
+	// agent-1 waits for agent-2, which waits for agent-3
+	var conflict *LockConflictError
+	if _, err := flm.AcquireLock("proj1", "b.go", "agent-1", "bead-1"); !errors.As(err, &conflict) || conflict.Held.AgentID != "agent-2" {
+		t.Fatalf("expected a conflict with agent-2, got %v", err)
+	}
+	if _, err := flm.AcquireLock("proj1", "c.go", "agent-2", "bead-2"); !errors.As(err, &conflict) {
+		t.Fatalf("expected a conflict with agent-3, got %v", err)
+	}
+
+	// agent-3 waiting for agent-1 would close the cycle
+	_, err := flm.AcquireLock("proj1", "a.go", "agent-3", "bead-3")
+	var deadlock *DeadlockError
+	if !errors.As(err, &deadlock) {
+		t.Fatalf("expected a deadlock, got %v", err)
+	}
+	if want := []string{"agent-3", "agent-1", "agent-2"}; !reflect.DeepEqual(deadlock.Cycle, want) {
+		t.Errorf("cycle = %v, want %v", deadlock.Cycle, want)
+	}
+	if len(deadlock.Released) != 1 || deadlock.Released[0].FilePath != "c.go" {
+		t.Errorf("expected agent-3's lock to be released, got %+v", deadlock.Released)
+	}
+
+	// With agent-3's lock gone, agent-2 can go on
+	if _, err := flm.AcquireLock("proj1", "c.go", "agent-2", "bead-2"); err != nil {
+		t.Errorf("agent-2 should get the released lock, got %v", err)
+	}
+}
+
+func TestLockFiles_RecordsLocksOnBead(t *testing.T) {
+	l, tmpDir := testLoom(t, func(cfg *config.Config) {
+		cfg.Agents.FileLockTimeout = 5 * time.Minute
+	})
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+	ctx := context.Background()
+
+	proj, err := l.CreateProject("locks", "git@github.com:acme/locks.git", "main", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	first, _ := l.CreateBead("First", "desc", models.BeadPriorityP2, "task", proj.ID)
+	second, _ := l.CreateBead("Second", "desc", models.BeadPriorityP2, "task", proj.ID)
+	actx1 := actions.ActionContext{AgentID: "agent-1", BeadID: first.ID, ProjectID: proj.ID}
+	actx2 := actions.ActionContext{AgentID: "agent-2", BeadID: second.ID, ProjectID: proj.ID}
+
+	if err := l.LockFiles(ctx, actx1, []string{"b.go", "a.go"}); err != nil {
+		t.Fatalf("LockFiles failed: %v", err)
+	}
+	got, _ := l.beadsManager.GetBead(first.ID)
+	if got.Context[beadFileLocksKey] != "a.go,b.go" {
+		t.Errorf("locks not recorded on the bead: %v", got.Context)
+	}
+
+	err = l.LockFiles(ctx, actx2, []string{"a.go"})
+	if err == nil || !strings.Contains(err.Error(), "already locked by agent agent-1") {
+		t.Fatalf("expected the second agent to be refused, got %v", err)
+	}
+	got, _ = l.beadsManager.GetBead(second.ID)
+	if !strings.Contains(got.Context[beadFileLockWaitKey], "a.go") {
+		t.Errorf("wait not recorded on the bead: %v", got.Context)
+	}
+
+	l.ReleaseFiles(ctx, actx1)
+	got, _ = l.beadsManager.GetBead(first.ID)
+	if got.Context[beadFileLocksKey] != "" {
+		t.Errorf("released locks still shown on the bead: %v", got.Context)
+	}
+	if err := l.LockFiles(ctx, actx2, []string{"a.go"}); err != nil {
+		t.Fatalf("LockFiles after release failed: %v", err)
+	}
+	got, _ = l.beadsManager.GetBead(second.ID)
+	if got.Context[beadFileLocksKey] != "a.go" || got.Context[beadFileLockWaitKey] != "" {
+		t.Errorf("expected the wait to be cleared, got %v", got.Context)
+	}
+}
@@ -0,0 +1,127 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+)
+
+// newGoldenPrompts opens the golden prompt suites over db and reruns them
+// when a provider's model or the routing configuration changes. Runs with
+// regressions are published, which puts them in the activity feed and
+// sends every user a critical notification. Without a database there is
+// nowhere to keep the suites.
+func newGoldenPrompts(db *database.Database, registry *provider.Registry, eb *eventbus.EventBus) *goldenprompts.Manager {
+	if db == nil || registry == nil {
+		return nil
+	}
+	mgr := goldenprompts.NewManager(db, &goldenCompleter{registry: registry}, goldenprompts.DefaultConfig())
+	if eb == nil {
+		return mgr
+	}
+
+	mgr.OnRegression(func(run *goldenprompts.Run) {
+		_ = eb.Publish(&eventbus.Event{
+			Type:      eventbus.EventTypeGoldenPromptRegression,
+			Source:    "golden-prompts",
+			ProjectID: run.ProjectID,
+			Data: map[string]interface{}{
+				"run_id":      run.ID,
+				"title":       fmt.Sprintf("%d golden prompt regression(s) in %s after %s", run.Regressions, run.ProjectID, strings.ReplaceAll(run.Trigger, "_", " ")),
+				"trigger":     run.Trigger,
+				"reason":      run.Reason,
+				"regressions": run.Regressions,
+				"failed":      run.Failed,
+			},
+		})
+	})
+
+	sub := eb.Subscribe("golden-prompts", func(event *eventbus.Event) bool {
+		switch event.Type {
+		case eventbus.EventTypeProviderRegistered, eventbus.EventTypeProviderUpdated, eventbus.EventTypeConfigUpdated:
+			return true
+		}
+		return false
+	})
+	go watchGoldenPromptChanges(mgr, sub.Channel)
+	return mgr
+}
+
+// watchGoldenPromptChanges tells mgr about model and routing changes.
+// provider.updated also reports request metrics, so a provider's model is
+// remembered and only a different one counts; the first model seen for a
+// provider is its baseline, which keeps startup from running the suites.
+func watchGoldenPromptChanges(mgr *goldenprompts.Manager, events <-chan *eventbus.Event) {
+	models := make(map[string]string)
+	for event := range events {
+		if event.Type == eventbus.EventTypeConfigUpdated {
+			mgr.Changed(goldenprompts.TriggerRouting, "configuration updated")
+			continue
+		}
+		providerID, _ := event.Data["provider_id"].(string)
+		model, _ := event.Data["model"].(string)
+		if providerID == "" || model == "" {
+			continue
+		}
+		previous, known := models[providerID]
+		models[providerID] = model
+		if known && previous != model {
+			mgr.Changed(goldenprompts.TriggerModelChange, fmt.Sprintf("%s changed from %s to %s", providerID, previous, model))
+		}
+	}
+}
+
+// GetGoldenPrompts returns the golden prompt regression suites
+func (a *Loom) GetGoldenPrompts() *goldenprompts.Manager {
+	return a.goldenPrompts
+}
+
+// goldenCompleter runs golden prompts on the registered providers
+type goldenCompleter struct {
+	registry *provider.Registry
+}
+
+// Targets returns the active providers and their models
+func (c *goldenCompleter) Targets() []goldenprompts.Target {
+	var targets []goldenprompts.Target
+	for _, p := range c.registry.ListActive() {
+		if p == nil || p.Config == nil {
+			continue
+		}
+		targets = append(targets, goldenprompts.Target{ProviderID: p.Config.ID, Model: p.Config.Model})
+	}
+	return targets
+}
+
+// Complete sends one golden prompt to a provider's current model
+func (c *goldenCompleter) Complete(ctx context.Context, providerID, systemPrompt, prompt string) (string, string, error) {
+	var messages []provider.ChatMessage
+	if systemPrompt != "" {
+		messages = append(messages, provider.ChatMessage{Role: "system", Content: systemPrompt})
+	}
+	messages = append(messages, provider.ChatMessage{Role: "user", Content: prompt})
+
+	model := ""
+	if p, err := c.registry.Get(providerID); err == nil && p.Config != nil {
+		model = p.Config.Model
+	}
+	resp, err := c.registry.SendChatCompletion(provider.WithPriority(ctx, provider.PriorityP3), providerID, &provider.ChatCompletionRequest{
+		Model:    model,
+		Messages: messages,
+	})
+	if err != nil {
+		return "", model, err
+	}
+	if len(resp.Choices) == 0 {
+		return "", model, fmt.Errorf("provider %s returned no choices", providerID)
+	}
+	if resp.Model != "" {
+		model = resp.Model
+	}
+	return resp.Choices[0].Message.Content, model, nil
+}
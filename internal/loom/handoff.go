@@ -0,0 +1,96 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// HandoffBead hands a bead from the agent working it to another persona
+// mid-task, e.g. a coder handing off to a reviewer. The handoff document is
+// appended to the bead's handoffs context and the bead selects the target
+// persona, optionally pinned to a model tier, so the dispatcher gives it to
+// one of that persona's agents next.
+func (a *Loom) HandoffBead(ctx context.Context, beadID, fromAgentID string, h actions.HandoffPayload) error {
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return fmt.Errorf("bead not found: %w", err)
+	}
+	if bead.Status == models.BeadStatusClosed {
+		return fmt.Errorf("bead %s is closed", beadID)
+	}
+	summary := strings.TrimSpace(h.Summary)
+	if summary == "" {
+		return fmt.Errorf("handoff summary must not be empty")
+	}
+	to := dispatch.QualifyPersonaName(h.ToPersona)
+	if _, err := a.personaManager.LoadPersona(to); err != nil {
+		return fmt.Errorf("unknown persona %s", to)
+	}
+	tier := strings.ToLower(strings.TrimSpace(h.ModelTier))
+	if err := persona.ValidateModelTier(tier); err != nil {
+		return err
+	}
+
+	from := ""
+	if a.agentManager != nil {
+		if ag, err := a.agentManager.GetAgent(fromAgentID); err == nil && ag != nil {
+			from = dispatch.QualifyPersonaName(ag.PersonaName)
+		}
+	}
+	if from == to && tier == "" {
+		return fmt.Errorf("bead is already with persona %s; hand off to another persona or model tier", to)
+	}
+
+	handoff := dispatch.Handoff{
+		FromAgentID:   fromAgentID,
+		FromPersona:   from,
+		ToPersona:     to,
+		ModelTier:     tier,
+		Summary:       summary,
+		OpenQuestions: h.OpenQuestions,
+		RelevantFiles: h.RelevantFiles,
+		HandedOffAt:   time.Now().UTC(),
+	}
+	handoffsJSON, err := json.Marshal(append(dispatch.BeadHandoffs(bead), handoff))
+	if err != nil {
+		return fmt.Errorf("failed to encode handoff: %w", err)
+	}
+
+	if _, err := a.UpdateBead(beadID, map[string]interface{}{
+		"status":      models.BeadStatusOpen,
+		"assigned_to": "",
+		"context": map[string]string{
+			dispatch.BeadHandoffsKey:  string(handoffsJSON),
+			dispatch.BeadPersonaKey:   to,
+			dispatch.BeadModelTierKey: tier,
+			"redispatch_requested":    "true",
+		},
+	}); err != nil {
+		return fmt.Errorf("failed to hand off bead: %w", err)
+	}
+
+	if a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadHandedOff, beadID, bead.ProjectID, map[string]interface{}{
+			"title":          bead.Title,
+			"agent_id":       fromAgentID,
+			"actor_id":       fromAgentID,
+			"actor_type":     "agent",
+			"from_persona":   from,
+			"to_persona":     to,
+			"model_tier":     tier,
+			"summary":        summary,
+			"open_questions": h.OpenQuestions,
+			"relevant_files": h.RelevantFiles,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestHandoffBead_RecordsDocumentAndSelectsPersona(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	proj, err := l.CreateProject("handoff", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	bead, err := l.CreateBead("Add login form", "desc", models.BeadPriorityP2, "task", proj.ID)
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	p, err := l.personaManager.LoadPersona("default/web-designer-engineer")
+	if err != nil {
+		t.Fatalf("LoadPersona failed: %v", err)
+	}
+	ag, err := l.agentManager.CreateAgent(context.Background(), "Coder", "default/web-designer-engineer", proj.ID, "web-designer-engineer", p)
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	if err := l.beadsManager.UpdateBead(bead.ID, map[string]interface{}{"status": models.BeadStatusInProgress, "assigned_to": ag.ID}); err != nil {
+		t.Fatalf("UpdateBead failed: %v", err)
+	}
+
+	err = l.HandoffBead(context.Background(), bead.ID, ag.ID, actions.HandoffPayload{
+		ToPersona:     "code-reviewer",
+		ModelTier:     "Large",
+		Summary:       "Form implemented, needs review",
+		OpenQuestions: []string{"Should the form remember the user?"},
+		RelevantFiles: []string{"web/login.html"},
+	})
+	if err != nil {
+		t.Fatalf("HandoffBead failed: %v", err)
+	}
+
+	got, err := l.beadsManager.GetBead(bead.ID)
+	if err != nil {
+		t.Fatalf("GetBead failed: %v", err)
+	}
+	if got.Status != models.BeadStatusOpen || got.AssignedTo != "" {
+		t.Errorf("expected the bead released, got status %s assigned to %q", got.Status, got.AssignedTo)
+	}
+	if got.Context[dispatch.BeadPersonaKey] != "default/code-reviewer" || got.Context[dispatch.BeadModelTierKey] != "large" {
+		t.Errorf("expected the bead to select the reviewer on a large model, got %v", got.Context)
+	}
+	handoffs := dispatch.BeadHandoffs(got)
+	if len(handoffs) != 1 || handoffs[0].FromPersona != "default/web-designer-engineer" || handoffs[0].RelevantFiles[0] != "web/login.html" {
+		t.Fatalf("unexpected handoffs %+v", handoffs)
+	}
+
+	// The reviewer hands back to the coder; the history keeps both
+	if err := l.HandoffBead(context.Background(), bead.ID, "", actions.HandoffPayload{ToPersona: "web-designer-engineer", Summary: "Two nits"}); err != nil {
+		t.Fatalf("second HandoffBead failed: %v", err)
+	}
+	got, _ = l.beadsManager.GetBead(bead.ID)
+	if handoffs := dispatch.BeadHandoffs(got); len(handoffs) != 2 || got.Context[dispatch.BeadModelTierKey] != "" {
+		t.Errorf("expected two handoffs and the tier pin cleared, got %+v (%v)", handoffs, got.Context)
+	}
+}
+
+func TestHandoffBead_Rejects(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	proj, err := l.CreateProject("handoff-rejects", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	bead, err := l.CreateBead("Fix bug", "desc", models.BeadPriorityP2, "task", proj.ID)
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	p, err := l.personaManager.LoadPersona("default/qa-engineer")
+	if err != nil {
+		t.Fatalf("LoadPersona failed: %v", err)
+	}
+	ag, err := l.agentManager.CreateAgent(context.Background(), "QA", "default/qa-engineer", proj.ID, "qa-engineer", p)
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		handoff actions.HandoffPayload
+	}{
+		{"unknown persona", actions.HandoffPayload{ToPersona: "astronaut", Summary: "x"}},
+		{"unknown tier", actions.HandoffPayload{ToPersona: "code-reviewer", ModelTier: "huge", Summary: "x"}},
+		{"own persona", actions.HandoffPayload{ToPersona: "qa-engineer", Summary: "x"}},
+		{"empty summary", actions.HandoffPayload{ToPersona: "code-reviewer", Summary: " "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := l.HandoffBead(context.Background(), bead.ID, ag.ID, tt.handoff); err == nil {
+				t.Errorf("expected %s to be rejected", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/issuesync"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// issueSyncAuthor is the comment author ID of comments imported from
+// trackers, which keeps them from being pushed back
+const issueSyncAuthor = "issue-sync"
+
+// newIssueSync opens the issue tracker sync over db for the projects whose
+// config names a tracker. Bead status changes and comments are pushed back
+// to their issues as they are published. Without a database there is
+// nowhere to keep the links between issues and beads.
+func newIssueSync(a *Loom, db *database.Database, cfg *config.Config) *issuesync.Manager {
+	if db == nil {
+		return nil
+	}
+	mgr := issuesync.NewManager(db, &issueSyncBeads{loom: a}, issuesync.Config{
+		Enabled:     cfg.IssueSync.Enabled,
+		Interval:    cfg.IssueSync.Interval,
+		PriorityMap: cfg.IssueSync.PriorityMap,
+	})
+	for _, p := range cfg.Projects {
+		if p.IssueTracker == nil {
+			continue
+		}
+		project := issuesync.Project{ProjectID: p.ID, Tracker: p.IssueTracker.Type, Labels: p.IssueTracker.Labels}
+		switch p.IssueTracker.Type {
+		case issuesync.TrackerGitHub:
+			project.Ref = p.IssueTracker.Repository
+			if project.Ref == "" {
+				project.Ref = githubRepository(p.GitRepo)
+			}
+			project.Client = &githubIssues{loom: a, projectID: p.ID, repository: project.Ref}
+		case issuesync.TrackerJira:
+			jira := cfg.IssueSync.Jira
+			project.Ref = p.IssueTracker.ProjectKey
+			project.Client = issuesync.NewJiraClient(jira.URL, jira.Email, jira.APIToken, project.Ref)
+		}
+		if err := mgr.AddProject(project); err != nil {
+			log.Printf("[IssueSync] Not syncing %s: %v", p.ID, err)
+		}
+	}
+	if a.eventBus == nil || !mgr.Enabled() {
+		return mgr
+	}
+
+	sub := a.eventBus.Subscribe("issue-sync", func(event *eventbus.Event) bool {
+		return event.Type == eventbus.EventTypeBeadStatusChange || event.Type == "comment.created"
+	})
+	go a.pushBeadChanges(mgr, sub.Channel)
+	return mgr
+}
+
+// pushBeadChanges pushes the status changes and comments of imported beads
+// to their issues
+func (a *Loom) pushBeadChanges(mgr *issuesync.Manager, events <-chan *eventbus.Event) {
+	for event := range events {
+		beadID, _ := event.Data["bead_id"].(string)
+		if beadID == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		var err error
+		if event.Type == eventbus.EventTypeBeadStatusChange {
+			var bead *models.Bead
+			if bead, err = a.beadsManager.GetBead(beadID); err == nil {
+				err = mgr.BeadChanged(ctx, beadID, bead.Status)
+			}
+		} else if author, _ := event.Data["author_id"].(string); author != issueSyncAuthor {
+			username, _ := event.Data["author_username"].(string)
+			content, _ := event.Data["content"].(string)
+			err = mgr.BeadCommented(ctx, beadID, username, content)
+		}
+		cancel()
+		if err != nil {
+			log.Printf("[IssueSync] Failed to push %s of bead %s: %v", event.Type, beadID, err)
+		}
+	}
+}
+
+// GetIssueSync returns the issue tracker sync
+func (a *Loom) GetIssueSync() *issuesync.Manager {
+	return a.issueSync
+}
+
+// SyncIssue applies an issue a tracker sent to the bead of the project
+// syncing with ref, a GitHub owner/name or a Jira project key
+func (a *Loom) SyncIssue(ctx context.Context, tracker, ref string, issue issuesync.Issue) (*issuesync.Link, string, error) {
+	if !a.issueSync.Enabled() {
+		return nil, issuesync.OutcomeSkipped, nil
+	}
+	projectID := a.issueSync.ProjectFor(tracker, ref)
+	if projectID == "" {
+		return nil, issuesync.OutcomeSkipped, nil
+	}
+	return a.issueSync.Apply(ctx, projectID, issue)
+}
+
+// IssueCommented adds a comment made on an issue of the project syncing
+// with ref to the issue's bead
+func (a *Loom) IssueCommented(tracker, ref, key, author, body string) error {
+	projectID := a.issueSync.ProjectFor(tracker, ref)
+	if projectID == "" {
+		return nil
+	}
+	return a.issueSync.IssueCommented(projectID, key, author, body)
+}
+
+// issueSyncBeads files and updates the beads of imported issues
+type issueSyncBeads struct {
+	loom *Loom
+}
+
+// CreateBead files a bead with the issue's labels and external ID
+func (b *issueSyncBeads) CreateBead(projectID string, f issuesync.Fields) (string, error) {
+	a := b.loom
+	bead, err := a.CreateBead(f.Title, f.Description, f.Priority, f.Type, projectID)
+	if err != nil {
+		return "", err
+	}
+	if err := a.beadsManager.UpdateBead(bead.ID, map[string]interface{}{
+		"tags":    f.Tags,
+		"context": f.Context,
+	}); err != nil {
+		return "", err
+	}
+	return bead.ID, nil
+}
+
+// UpdateBead applies an issue's changes, publishing a status change
+func (b *issueSyncBeads) UpdateBead(beadID string, f issuesync.Fields) error {
+	updates := map[string]interface{}{
+		"title":       f.Title,
+		"description": f.Description,
+		"priority":    f.Priority,
+		"type":        f.Type,
+		"tags":        f.Tags,
+		"context":     f.Context,
+	}
+	if f.Status != "" {
+		updates["status"] = f.Status
+	}
+	_, err := b.loom.UpdateBead(beadID, updates)
+	return err
+}
+
+// AddComment adds a tracker comment to a bead
+func (b *issueSyncBeads) AddComment(beadID, author, body string) error {
+	if b.loom.commentsManager == nil {
+		return fmt.Errorf("comments not available")
+	}
+	_, err := b.loom.commentsManager.CreateComment(beadID, issueSyncAuthor, author, body, "")
+	return err
+}
+
+// githubIssues reads and writes a repository's issues through the gh CLI
+type githubIssues struct {
+	loom       *Loom
+	projectID  string
+	repository string
+}
+
+// Issues returns the repository's issues updated since
+func (g *githubIssues) Issues(ctx context.Context, since time.Time) ([]issuesync.Issue, error) {
+	command := fmt.Sprintf("gh issue list --repo %s --state all --limit 500 --json number,title,body,state,labels,url,updatedAt", shellQuote(g.repository))
+	if !since.IsZero() {
+		command += " --search " + shellQuote("updated:>="+since.UTC().Format(time.RFC3339))
+	}
+	out, err := g.gh(ctx, command)
+	if err != nil {
+		return nil, err
+	}
+	var list []struct {
+		Number int    `json:"number"`
+		Title  string `json:"title"`
+		Body   string `json:"body"`
+		State  string `json:"state"`
+		Labels []struct {
+			Name string `json:"name"`
+		} `json:"labels"`
+		URL       string    `json:"url"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("failed to decode issues: %w", err)
+	}
+	issues := make([]issuesync.Issue, 0, len(list))
+	for _, i := range list {
+		issue := issuesync.Issue{
+			Key:       fmt.Sprint(i.Number),
+			Title:     i.Title,
+			Body:      i.Body,
+			State:     issuesync.GitHubState(i.State),
+			URL:       i.URL,
+			UpdatedAt: i.UpdatedAt,
+		}
+		for _, l := range i.Labels {
+			issue.Labels = append(issue.Labels, l.Name)
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
+
+// SetState closes or reopens an issue. GitHub has no in-progress state,
+// so in_progress only reopens a closed issue.
+func (g *githubIssues) SetState(ctx context.Context, key, state string) error {
+	if state == issuesync.StateClosed {
+		_, err := g.gh(ctx, fmt.Sprintf("gh issue close %s --repo %s", shellQuote(key), shellQuote(g.repository)))
+		return err
+	}
+	out, err := g.gh(ctx, fmt.Sprintf("gh issue view %s --repo %s --json state", shellQuote(key), shellQuote(g.repository)))
+	if err != nil {
+		return err
+	}
+	var current struct {
+		State string `json:"state"`
+	}
+	if err := json.Unmarshal([]byte(out), &current); err != nil {
+		return fmt.Errorf("failed to decode issue: %w", err)
+	}
+	if issuesync.GitHubState(current.State) != issuesync.StateClosed {
+		return nil
+	}
+	_, err = g.gh(ctx, fmt.Sprintf("gh issue reopen %s --repo %s", shellQuote(key), shellQuote(g.repository)))
+	return err
+}
+
+// Comment adds a comment to an issue
+func (g *githubIssues) Comment(ctx context.Context, key, body string) error {
+	_, err := g.gh(ctx, fmt.Sprintf("gh issue comment %s --repo %s --body %s", shellQuote(key), shellQuote(g.repository), shellQuote(body)))
+	return err
+}
+
+// gh runs a gh command for the project and returns its output
+func (g *githubIssues) gh(ctx context.Context, command string) (string, error) {
+	res, err := g.loom.ExecuteCommand(ctx, executor.ExecuteCommandRequest{
+		AgentID:   issueSyncAuthor,
+		ProjectID: g.projectID,
+		Command:   command,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !res.Success {
+		msg := strings.TrimSpace(res.Stderr)
+		if msg == "" {
+			msg = res.Error
+		}
+		return "", fmt.Errorf("gh failed: %s", msg)
+	}
+	return res.Stdout, nil
+}
+
+// githubRepository returns the owner/name of a GitHub remote such as
+// git@github.com:acme/widgets.git or https://github.com/acme/widgets
+func githubRepository(remote string) string {
+	remote = strings.TrimSuffix(strings.TrimRight(remote, "/"), ".git")
+	if i := strings.LastIndex(remote, ":"); i >= 0 && !strings.Contains(remote[i:], "//") {
+		remote = "/" + remote[i+1:]
+	}
+	parts := strings.Split(remote, "/")
+	if len(parts) < 2 || parts[len(parts)-2] == "" || parts[len(parts)-1] == "" {
+		return ""
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// shellQuote quotes s as a single shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,100 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/issuesync"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// recordingTracker records the states and comments pushed to it
+type recordingTracker struct {
+	states   map[string]string
+	comments []string
+}
+
+func (r *recordingTracker) Issues(ctx context.Context, since time.Time) ([]issuesync.Issue, error) {
+	return nil, nil
+}
+
+func (r *recordingTracker) SetState(ctx context.Context, key, state string) error {
+	r.states[key] = state
+	return nil
+}
+
+func (r *recordingTracker) Comment(ctx context.Context, key, body string) error {
+	r.comments = append(r.comments, body)
+	return nil
+}
+
+func TestSyncIssue_ImportsIssuesAndPushesBeadChanges(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+	ctx := context.Background()
+
+	proj, err := l.CreateProject("issues", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	tracker := &recordingTracker{states: make(map[string]string)}
+	l.issueSync = issuesync.NewManager(l.database, &issueSyncBeads{loom: l}, issuesync.Config{Enabled: true})
+	if err := l.issueSync.AddProject(issuesync.Project{ProjectID: proj.ID, Tracker: issuesync.TrackerJira, Ref: "OPS", Client: tracker}); err != nil {
+		t.Fatalf("AddProject failed: %v", err)
+	}
+
+	if _, outcome, _ := l.SyncIssue(ctx, issuesync.TrackerJira, "OTHER", issuesync.Issue{Key: "OTHER-1"}); outcome != issuesync.OutcomeSkipped {
+		t.Errorf("expected an issue of an unsynced project to be skipped, got %s", outcome)
+	}
+	link, outcome, err := l.SyncIssue(ctx, issuesync.TrackerJira, "ops", issuesync.Issue{
+		Key: "OPS-7", Title: "Disk full", State: issuesync.StateOpen, Priority: "Highest",
+		Labels: []string{"infra"}, URL: "https://jira.example/browse/OPS-7", UpdatedAt: time.Now(),
+	})
+	if err != nil || outcome != issuesync.OutcomeImported {
+		t.Fatalf("SyncIssue = %s, %v", outcome, err)
+	}
+	bead, err := l.beadsManager.GetBead(link.BeadID)
+	if err != nil {
+		t.Fatalf("bead not filed: %v", err)
+	}
+	if bead.ProjectID != proj.ID || bead.Priority != models.BeadPriorityP0 || len(bead.Tags) != 1 ||
+		bead.Context[issuesync.ContextExternalID] != "OPS-7" || bead.Context[issuesync.ContextURL] == "" {
+		t.Fatalf("unexpected bead %+v", bead)
+	}
+
+	// Bead changes are pushed as they are published
+	events := make(chan *eventbus.Event, 3)
+	events <- &eventbus.Event{Type: eventbus.EventTypeBeadStatusChange, Data: map[string]interface{}{"bead_id": bead.ID}}
+	events <- &eventbus.Event{Type: "comment.created", Data: map[string]interface{}{"bead_id": bead.ID, "author_id": "u1", "author_username": "alice", "content": "On it"}}
+	events <- &eventbus.Event{Type: "comment.created", Data: map[string]interface{}{"bead_id": bead.ID, "author_id": issueSyncAuthor, "author_username": "jira:bob", "content": "Thanks"}}
+	close(events)
+	if _, err := l.UpdateBead(bead.ID, map[string]interface{}{"status": models.BeadStatusClosed}); err != nil {
+		t.Fatalf("UpdateBead failed: %v", err)
+	}
+	l.pushBeadChanges(l.issueSync, events)
+	if tracker.states["OPS-7"] != issuesync.StateClosed {
+		t.Errorf("expected the issue to be closed, got %v", tracker.states)
+	}
+	if len(tracker.comments) != 1 || tracker.comments[0] != issuesync.CommentPrefix+"alice: On it" {
+		t.Errorf("expected only the local comment to be pushed, got %v", tracker.comments)
+	}
+}
+
+func TestGitHubRepository(t *testing.T) {
+	tests := map[string]string{
+		"git@github.com:acme/widgets.git":       "acme/widgets",
+		"https://github.com/acme/widgets":       "acme/widgets",
+		"ssh://git@github.com:22/acme/widgets/": "acme/widgets",
+		".":                                     "",
+	}
+	for remote, want := range tests {
+		if got := githubRepository(remote); got != want {
+			t.Errorf("githubRepository(%q) = %q, want %q", remote, got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/knowledge"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// newKnowledgeGraph opens the knowledge graph over db, indexing the bead
+// commits in each project's working copy and the project's lessons.
+// Without a database there are no lessons to link.
+func newKnowledgeGraph(a *Loom, db *database.Database, cfg config.KnowledgeConfig) *knowledge.Graph {
+	if db == nil {
+		return nil
+	}
+	g := knowledge.New(db, knowledge.Config{Interval: cfg.IndexInterval})
+
+	var mu sync.Mutex
+	services := make(map[string]*git.GitService)
+	g.SetSources(knowledge.Sources{
+		Projects: func() []string {
+			var ids []string
+			for _, p := range a.projectManager.ListProjects() {
+				ids = append(ids, p.ID)
+			}
+			return ids
+		},
+		Commits: func(ctx context.Context, projectID string) ([]git.CommitMetadata, error) {
+			p, err := a.projectManager.GetProject(projectID)
+			if err != nil || p.WorkDir == "" {
+				return nil, nil
+			}
+			mu.Lock()
+			gs, ok := services[projectID]
+			if !ok {
+				if gs, err = git.NewGitService(p.WorkDir, p.ID); err != nil {
+					mu.Unlock()
+					// Projects not cloned yet have no history to read
+					return nil, nil
+				}
+				services[projectID] = gs
+			}
+			mu.Unlock()
+			return gs.LoomCommits(ctx, cfg.MaxCommits)
+		},
+		Lessons: func(projectID string) ([]*models.Lesson, error) {
+			return db.GetLessonsForProject(projectID, 200, 0)
+		},
+		Mentions: dispatch.ExtractFilePaths,
+	})
+	return g
+}
+
+// GetKnowledgeGraph returns the knowledge graph
+func (a *Loom) GetKnowledgeGraph() *knowledge.Graph {
+	return a.knowledge
+}
+
+// KnowledgeLesson is a lesson the knowledge graph links to a path, with
+// the files, and beads, that linked it
+type KnowledgeLesson struct {
+	*models.Lesson
+	Files  []string `json:"files"`
+	Beads  []string `json:"beads,omitempty"`
+	Direct bool     `json:"direct"`
+}
+
+// LessonsTouching returns a project's lessons linked to the files at or
+// under the paths, most closely linked first
+func (a *Loom) LessonsTouching(projectID string, paths []string) ([]*KnowledgeLesson, error) {
+	if a.knowledge == nil || a.database == nil {
+		return nil, fmt.Errorf("knowledge graph not available")
+	}
+	hits, err := a.knowledge.LessonsTouching(projectID, paths...)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, len(hits))
+	for i, h := range hits {
+		ids[i] = h.LessonID
+	}
+	lessons, err := a.database.GetLessonsByID(ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Lesson, len(lessons))
+	for _, l := range lessons {
+		byID[l.ID] = l
+	}
+	list := make([]*KnowledgeLesson, 0, len(hits))
+	for _, h := range hits {
+		if l, ok := byID[h.LessonID]; ok {
+			list = append(list, &KnowledgeLesson{Lesson: l, Files: h.Files, Beads: h.Beads, Direct: h.Direct})
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,60 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/lsp"
+)
+
+// lspService returns the language servers of the project an action runs
+// in, working on the project's working copy
+func (a *Loom) lspService(ctx context.Context) (*lsp.LSPService, error) {
+	projectID := actions.ProjectIDFromContext(ctx)
+	if projectID == "" {
+		return nil, fmt.Errorf("no project to query language servers for")
+	}
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+	if p.WorkDir == "" {
+		return nil, fmt.Errorf("project %s has no working copy", projectID)
+	}
+	return a.languageServers.Service(p.ID, p.WorkDir)
+}
+
+// LanguageServers describes the language servers running for a project
+func (a *Loom) LanguageServers(projectID string) ([]lsp.ServerStatus, error) {
+	if _, err := a.projectManager.GetProject(projectID); err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+	return a.languageServers.Servers(projectID), nil
+}
+
+// StopLanguageServers stops the language servers of a project, e.g. to
+// pick up a changed toolchain; the next agent request starts them again
+func (a *Loom) StopLanguageServers(projectID, actor string) error {
+	if _, err := a.projectManager.GetProject(projectID); err != nil {
+		return fmt.Errorf("project not found: %w", err)
+	}
+	stopped := a.languageServers.Servers(projectID)
+	if err := a.languageServers.Stop(projectID); err != nil {
+		return err
+	}
+	languages := make([]string, 0, len(stopped))
+	for _, s := range stopped {
+		languages = append(languages, s.Language)
+	}
+	audit.Record(audit.Event{
+		Actor:     actor,
+		Action:    "project.language_servers.stop",
+		Resource:  projectID,
+		ProjectID: projectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details:   map[string]interface{}{"languages": languages},
+	})
+	return nil
+}
@@ -0,0 +1,47 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+)
+
+func TestLanguageServerProjects(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	proj, err := l.CreateProject("nav", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	ctx := actions.WithProjectID(context.Background(), proj.ID)
+	if _, err := l.lspService(ctx); err == nil {
+		t.Error("expected an error for a project without a working copy")
+	}
+	if _, err := l.lspService(context.Background()); err == nil {
+		t.Error("expected an error without a project")
+	}
+
+	proj.WorkDir = t.TempDir()
+	service, err := l.lspService(ctx)
+	if err != nil {
+		t.Fatalf("lspService failed: %v", err)
+	}
+	if again, _ := l.lspService(ctx); again != service {
+		t.Error("expected the project's language servers to be reused")
+	}
+	if servers, err := l.LanguageServers(proj.ID); err != nil || len(servers) != 0 {
+		t.Errorf("LanguageServers = %+v, %v", servers, err)
+	}
+	if err := l.StopLanguageServers(proj.ID, "admin"); err != nil {
+		t.Fatalf("StopLanguageServers failed: %v", err)
+	}
+	if after, _ := l.lspService(ctx); after == service {
+		t.Error("expected stopped servers to be started afresh")
+	}
+	if _, err := l.LanguageServers("missing"); err == nil {
+		t.Error("expected an error for an unknown project")
+	}
+}
@@ -0,0 +1,102 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/memory"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// noAdjudicator configures contradictions to be found by the polarity
+// heuristic alone
+const noAdjudicator = "none"
+
+// newContradictionDetector returns the detector superseding contradicted
+// lessons, with the configured provider's model adjudicating. Without a
+// database there are no lessons to compare.
+func newContradictionDetector(a *Loom, db *database.Database, cfg config.LessonsConfig) *memory.ContradictionDetector {
+	if db == nil {
+		return nil
+	}
+	var adjudicator memory.Adjudicator
+	if cfg.AdjudicatorProvider != noAdjudicator {
+		adjudicator = memory.NewLLMAdjudicator(func(ctx context.Context, systemPrompt, prompt string) (string, error) {
+			return a.completeForAdjudication(ctx, cfg.AdjudicatorProvider, systemPrompt, prompt)
+		})
+	}
+	return memory.NewContradictionDetector(db, adjudicator, memory.ContradictionConfig{
+		Similarity: cfg.ContradictionSimilarity,
+	})
+}
+
+// completeForAdjudication sends a prompt to the provider's current model,
+// or to the first active provider's when providerID is empty
+func (a *Loom) completeForAdjudication(ctx context.Context, providerID, systemPrompt, prompt string) (string, error) {
+	var p *provider.RegisteredProvider
+	if providerID != "" {
+		rp, err := a.providerRegistry.Get(providerID)
+		if err != nil {
+			return "", err
+		}
+		p = rp
+	} else {
+		for _, rp := range a.providerRegistry.ListActive() {
+			if rp != nil && rp.Config != nil {
+				p = rp
+				break
+			}
+		}
+	}
+	if p == nil || p.Config == nil {
+		return "", fmt.Errorf("no active provider to adjudicate lessons")
+	}
+
+	resp, err := a.providerRegistry.SendChatCompletion(provider.WithPriority(ctx, provider.PriorityP3), p.Config.ID, &provider.ChatCompletionRequest{
+		Model: p.Config.Model,
+		Messages: []provider.ChatMessage{
+			{Role: "system", Content: systemPrompt},
+			{Role: "user", Content: prompt},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("provider %s returned no choices", p.Config.ID)
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+// ListSupersededLessons returns a project's lessons superseded by ones
+// contradicting them, most recently superseded first
+func (a *Loom) ListSupersededLessons(projectID string, limit int) ([]*models.Lesson, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	return a.database.ListSupersededLessons(projectID, limit)
+}
+
+// ReinstateLesson puts a superseded lesson of a project back into prompts,
+// for when a contradiction was misjudged
+func (a *Loom) ReinstateLesson(projectID, lessonID string) (*models.Lesson, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	lessons, err := a.database.GetLessonsByID([]string{lessonID})
+	if err != nil {
+		return nil, err
+	}
+	if len(lessons) == 0 || lessons[0].ProjectID != projectID {
+		return nil, fmt.Errorf("lesson not found: %s", lessonID)
+	}
+	if err := a.database.ReinstateLesson(lessonID); err != nil {
+		return nil, err
+	}
+	l := lessons[0]
+	l.SupersededBy, l.SupersededAt = "", nil
+	return l, nil
+}
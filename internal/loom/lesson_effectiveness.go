@@ -0,0 +1,17 @@
+package loom
+
+import (
+	"fmt"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// LessonEffectiveness reports a project's lessons that went into at least
+// minInjections dispatches, up to limit each most and least helpful, by how
+// much more often those dispatches succeeded than the project's overall
+func (a *Loom) LessonEffectiveness(projectID string, minInjections, limit int) (*models.LessonEffectivenessReport, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	return a.database.LessonEffectivenessReport(projectID, minInjections, limit)
+}
@@ -0,0 +1,30 @@
+package loom
+
+import (
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+)
+
+// GetLiveMetrics returns the rolling request and cache totals that request
+// loggers and the response cache count into
+func (a *Loom) GetLiveMetrics() *analytics.LiveMetrics {
+	return a.liveMetrics
+}
+
+// LiveMetrics returns the rolling totals as of now with the current agent
+// counts. Agents are counted from the worker manager's in-memory list.
+func (a *Loom) LiveMetrics(now time.Time) *analytics.LiveSnapshot {
+	s := a.liveMetrics.Snapshot(now)
+	if a.agentManager == nil {
+		return s
+	}
+	s.AgentsByStatus = make(map[string]int)
+	for _, ag := range a.agentManager.ListAgents() {
+		s.AgentsByStatus[ag.Status]++
+		if ag.Status == "working" {
+			s.ActiveAgents++
+		}
+	}
+	return s
+}
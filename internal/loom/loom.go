@@ -18,16 +18,31 @@ import (
 	"github.com/jordanhubbard/loom/internal/activity"
 	"github.com/jordanhubbard/loom/internal/agent"
 	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/artifacts"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/backup"
 	"github.com/jordanhubbard/loom/internal/beads"
+	"github.com/jordanhubbard/loom/internal/benchmark"
+	"github.com/jordanhubbard/loom/internal/chat"
+	"github.com/jordanhubbard/loom/internal/cistatus"
 	"github.com/jordanhubbard/loom/internal/comments"
+	"github.com/jordanhubbard/loom/internal/costestimate"
 	"github.com/jordanhubbard/loom/internal/database"
 	"github.com/jordanhubbard/loom/internal/decision"
+	"github.com/jordanhubbard/loom/internal/demo"
 	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/evaluation"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
 	"github.com/jordanhubbard/loom/internal/executor"
 	"github.com/jordanhubbard/loom/internal/files"
 	"github.com/jordanhubbard/loom/internal/gitops"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/issuesync"
 	"github.com/jordanhubbard/loom/internal/keymanager"
+	"github.com/jordanhubbard/loom/internal/knowledge"
 	"github.com/jordanhubbard/loom/internal/logging"
+	"github.com/jordanhubbard/loom/internal/lsp"
+	"github.com/jordanhubbard/loom/internal/memory"
 	"github.com/jordanhubbard/loom/internal/metrics"
 	"github.com/jordanhubbard/loom/internal/modelcatalog"
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
@@ -35,16 +50,26 @@ import (
 	"github.com/jordanhubbard/loom/internal/notifications"
 	"github.com/jordanhubbard/loom/internal/observability"
 	"github.com/jordanhubbard/loom/internal/openclaw"
+	"github.com/jordanhubbard/loom/internal/org"
 	"github.com/jordanhubbard/loom/internal/orgchart"
 	"github.com/jordanhubbard/loom/internal/patterns"
 	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/internal/plugin"
+	"github.com/jordanhubbard/loom/internal/policy"
+	"github.com/jordanhubbard/loom/internal/pricing"
 	"github.com/jordanhubbard/loom/internal/project"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
 	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/prreview"
+	"github.com/jordanhubbard/loom/internal/reports"
 	"github.com/jordanhubbard/loom/internal/routing"
+	"github.com/jordanhubbard/loom/internal/scheduler"
+	"github.com/jordanhubbard/loom/internal/search"
 	"github.com/jordanhubbard/loom/internal/temporal"
 	temporalactivities "github.com/jordanhubbard/loom/internal/temporal/activities"
 	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
 	"github.com/jordanhubbard/loom/internal/temporal/workflows"
+	"github.com/jordanhubbard/loom/internal/verification"
 	"github.com/jordanhubbard/loom/internal/workflow"
 	"github.com/jordanhubbard/loom/pkg/config"
 	"github.com/jordanhubbard/loom/pkg/models"
@@ -64,6 +89,7 @@ type Loom struct {
 	agentManager        *agent.WorkerManager
 	actionRouter        *actions.Router
 	projectManager      *project.Manager
+	orgManager          *org.Manager
 	personaManager      *persona.Manager
 	beadsManager        *beads.Manager
 	decisionManager     *decision.Manager
@@ -72,13 +98,39 @@ type Loom struct {
 	providerRegistry    *provider.Registry
 	database            *database.Database
 	dispatcher          *dispatch.Dispatcher
+	lessonsProvider     *dispatch.LessonsProvider
+	reembedder          *memory.Reembedder
+	embeddingCache      *memory.EmbeddingCache
+	knowledge           *knowledge.Graph
+	chats               *chat.Manager
+	fileExpertise       *dispatch.FileExpertiseProvider
+	artifactRecorder    *artifacts.Recorder
+	eventWebhooks       *eventhooks.Manager
+	reportScheduler     *reports.Manager
+	backups             *backup.Manager
+	demo                *demo.Manager
+	panels              *plugin.PanelRegistry
+	plugins             *plugin.Loader
+	policyEngine        *policy.Engine
+	goldenPrompts       *goldenprompts.Manager
+	prReviews           *prreview.Manager
+	evaluation          *evaluation.Manager
+	benchmarks          *benchmark.Manager
+	verifier            *verification.Verifier
+	ciStatus            *cistatus.Manager
+	issueSync           *issuesync.Manager
+	search              *search.Manager
+	projectTemplates    *projecttemplates.Manager
+	auditLogger         *audit.Logger
 	eventBus            *eventbus.EventBus
 	temporalManager     *temporal.Manager
+	scheduler           *scheduler.Scheduler // Embedded alternative to temporalManager
 	modelCatalog        *modelcatalog.Catalog
 	gitopsManager       *gitops.Manager
 	shellExecutor       *executor.ShellExecutor
 	logManager          *logging.Manager
 	activityManager     *activity.Manager
+	activityRetention   *activity.Retention
 	notificationManager *notifications.Manager
 	commentsManager     *comments.Manager
 	motivationRegistry  *motivation.Registry
@@ -87,13 +139,25 @@ type Loom struct {
 	workflowEngine      *workflow.Engine
 	patternManager      *patterns.Manager
 	metrics             *metrics.Metrics
+	liveMetrics         *analytics.LiveMetrics
+	pricing             *pricing.Catalog
+	costEstimator       *costestimate.Estimator
 	keyManager          *keymanager.KeyManager
+	deciderAuthorizer   func(userID, permission, projectID string) bool // optional; see SetDeciderAuthorizer
 	doltCoordinator     *beads.DoltCoordinator
 	openclawClient      *openclaw.Client
 	openclawBridge      *openclaw.Bridge
 	readinessMu         sync.Mutex
 	readinessCache      map[string]projectReadinessState
 	readinessFailures   map[string]time.Time
+	deprecationMu       sync.Mutex
+	deprecationWarnedAt map[string]time.Time
+	configPath          string
+	reloadMu            sync.Mutex
+	reloadSections      []reloadSection
+	lastReload          *ReloadResult
+	onboardingScans     sync.Map // Project IDs with an onboarding scan running
+	languageServers     *lsp.Pool
 }
 
 // New creates a new Loom instance
@@ -105,9 +169,19 @@ func New(cfg *config.Config) (*Loom, error) {
 
 	providerRegistry := provider.NewRegistry()
 
-	// Initialize Temporal manager if configured
+	if cfg.Security.AuditLogPath != "" {
+		if err := observability.SetSecurityAuditLog(cfg.Security.AuditLogPath); err != nil {
+			log.Printf("Warning: failed to open security audit log: %v", err)
+		}
+	}
+
+	// Both backends run activities under the configured policies
+	workflows.SetActivityPolicies(cfg.Temporal.Activities)
+
+	// Initialize Temporal manager if configured, unless the embedded
+	// scheduler runs the workflows
 	var temporalMgr *temporal.Manager
-	if cfg.Temporal.Host != "" {
+	if cfg.Temporal.Host != "" && cfg.Scheduler.Backend != "embedded" {
 		var err error
 		temporalMgr, err = temporal.NewManager(&cfg.Temporal)
 		if err != nil {
@@ -133,12 +207,20 @@ func New(cfg *config.Config) (*Loom, error) {
 		}
 	} else if cfg.Database.Type == "postgres" && cfg.Database.DSN != "" {
 		var err error
-		db, err = database.NewPostgres(cfg.Database.DSN)
+		db, err = database.NewPostgres(cfg.Database.DSN, database.PoolConfig{
+			MaxOpenConns:    cfg.Database.MaxOpenConns,
+			MaxIdleConns:    cfg.Database.MaxIdleConns,
+			ConnMaxLifetime: cfg.Database.ConnMaxLifetime,
+			ConnMaxIdleTime: cfg.Database.ConnMaxIdleTime,
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to initialize postgres: %w", err)
 		}
 	}
 
+	auditLogger := newAuditLogger(db)
+	audit.SetDefault(auditLogger)
+
 	// Initialize model catalog from config or use defaults.
 	// Priority: 1) config.yaml preferred_models, 2) database override, 3) hardcoded defaults
 	modelCatalog := modelcatalog.DefaultCatalog()
@@ -169,6 +251,29 @@ func New(cfg *config.Config) (*Loom, error) {
 		modelCatalog.Replace(specs)
 		log.Printf("[ModelCatalog] Loaded %d preferred models from config.yaml", len(specs))
 	}
+	if len(cfg.Models.Deprecations) > 0 {
+		entries := make([]internalmodels.ModelDeprecation, 0, len(cfg.Models.Deprecations))
+		for _, d := range cfg.Models.Deprecations {
+			entries = append(entries, internalmodels.ModelDeprecation{
+				Model:        d.Model,
+				DeprecatedOn: d.DeprecatedOn,
+				EndOfLife:    d.EndOfLife,
+				Replacement:  d.Replacement,
+				Notes:        d.Notes,
+			})
+		}
+		if err := modelCatalog.AddDeprecations(entries); err != nil {
+			log.Printf("[ModelCatalog] Ignoring model deprecations from config: %v", err)
+		}
+	}
+	if len(cfg.Models.Metadata) > 0 {
+		if err := modelCatalog.AddModelMetadata(modelMetadataFromConfig(cfg.Models.Metadata), modelcatalog.MetadataSourceConfig); err != nil {
+			log.Printf("[ModelCatalog] Ignoring model metadata from config: %v", err)
+		}
+	}
+	providerRegistry.SetModelMetadata(modelCatalog)
+	prices := newPricing(db, cfg.Models)
+	providerRegistry.SetPricer(prices)
 	// Database can override config (for runtime updates via API)
 	if db != nil {
 		if raw, ok, err := db.GetConfigValue(modelCatalogKey); err == nil && ok {
@@ -217,6 +322,7 @@ func New(cfg *config.Config) (*Loom, error) {
 	var shellExec *executor.ShellExecutor
 	if db != nil {
 		shellExec = executor.NewShellExecutor(db.DB())
+		shellExec.SetSandboxPolicy(newSandboxPolicy(cfg))
 	}
 	var logMgr *logging.Manager
 	if db != nil {
@@ -241,18 +347,32 @@ func New(cfg *config.Config) (*Loom, error) {
 	var commentsMgr *comments.Manager
 	if db != nil {
 		activityMgr = activity.NewManager(db, eb)
+		activityMgr.SetAggregationRules(activityAggregationRules(cfg.Activity.Aggregation))
 		notificationMgr = notifications.NewManager(db, activityMgr)
+		if cfg.Notifications.Push.Enabled() {
+			push, err := notifications.NewPushDeliverer(db, cfg.Notifications.Push)
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure push notifications: %w", err)
+			}
+			if err := notificationMgr.RegisterDeliverer(notifications.ChannelPush, push); err != nil {
+				return nil, err
+			}
+		}
 		commentsMgr = comments.NewManager(db, notificationMgr, eb)
 	}
 
 	// Initialize pattern manager and analytics logger if database is available
 	var patternMgr *patterns.Manager
+	var analyticsLogger *analytics.Logger
 	if db != nil {
 		analyticsStorage, err := analytics.NewDatabaseStorage(db.DB())
 		if err == nil && analyticsStorage != nil {
 			patternMgr = patterns.NewManager(analyticsStorage, nil)
+			patternMgr.SetPricer(prices)
 			// Wire analytics logger to WorkerManager so LLM completions are logged
-			agentMgr.SetAnalyticsLogger(analytics.NewLogger(analyticsStorage, analytics.DefaultPrivacyConfig()))
+			analyticsLogger = analytics.NewLogger(analyticsStorage, analytics.DefaultPrivacyConfig())
+			analyticsLogger.SetPricer(prices)
+			agentMgr.SetAnalyticsLogger(analyticsLogger)
 		}
 	}
 
@@ -272,6 +392,7 @@ func New(cfg *config.Config) (*Loom, error) {
 
 	arb := &Loom{
 		config:              cfg,
+		languageServers:     lsp.NewPool(cfg.LanguageServers.IdleTimeout, cfg.LanguageServers.Servers),
 		agentManager:        agentMgr,
 		projectManager:      project.NewManager(),
 		personaManager:      persona.NewManager(personaPath),
@@ -281,6 +402,7 @@ func New(cfg *config.Config) (*Loom, error) {
 		orgChartManager:     orgchart.NewManager(),
 		providerRegistry:    providerRegistry,
 		database:            db,
+		auditLogger:         auditLogger,
 		eventBus:            eb,
 		temporalManager:     temporalMgr,
 		modelCatalog:        modelCatalog,
@@ -289,41 +411,114 @@ func New(cfg *config.Config) (*Loom, error) {
 		logManager:          logMgr,
 		activityManager:     activityMgr,
 		notificationManager: notificationMgr,
+		eventWebhooks:       newEventWebhooks(db, eb, activityMgr),
 		commentsManager:     commentsMgr,
 		motivationRegistry:  motivationRegistry,
 		idleDetector:        idleDetector,
 		workflowEngine:      workflowEngine,
 		patternManager:      patternMgr,
 		metrics:             metrics.NewMetrics(),
+		liveMetrics:         analytics.NewLiveMetrics(),
+		pricing:             prices,
 		doltCoordinator:     doltCoord,
 		openclawClient:      ocClient,
 		openclawBridge:      ocBridge,
 	}
 
+	gitRouter := actions.NewProjectGitRouter(gitopsMgr)
+	gitRouter.SetSecretsGate(&secretsGate{loom: arb})
+	gitRouter.SetCommitGate(&commitGate{loom: arb})
+	arb.demo = demo.NewManager(arb)
 	actionRouter := &actions.Router{
-		Beads:     arb,
-		Closer:    arb,
-		Escalator: arb,
-		Commands:  arb,
-		Files:     files.NewManager(gitopsMgr),
-		Git:       actions.NewProjectGitRouter(gitopsMgr),
-		Logger:    arb,
-		Workflow:  arb,
-		BeadType:  "task",
-		DefaultP0: true,
-	}
+		Beads:        arb,
+		Closer:       arb,
+		Escalator:    arb,
+		Handoffs:     arb,
+		Commands:     arb,
+		Analyzer:     arb,
+		LSP:          actions.NewProjectLSPAdapter(arb.lspService),
+		Files:        files.NewManager(gitopsMgr),
+		Git:          gitRouter,
+		Logger:       arb,
+		Workflow:     arb,
+		PullRequests: arb.demo,
+		Reviewer:     arb,
+		Costs:        arb,
+		Notes:        arb,
+		CI:           arb,
+		Locks:        arb,
+		BeadType:     "task",
+		DefaultP0:    true,
+	}
+	arb.orgManager = newOrgManager(db)
+	if activityMgr != nil {
+		activityMgr.SetOrgResolver(arb.activityOrg)
+	}
+	if notificationMgr != nil {
+		arb.configureEscalation(notificationMgr, cfg.Notifications.Escalation)
+	}
+	arb.policyEngine = newPolicyEngine(db, cfg.Policy)
+	policyGate := newPolicyGate(arb, db)
+	actionRouter.Policy = policyGate
 	arb.actionRouter = actionRouter
+	arb.goldenPrompts = newGoldenPrompts(db, arb.providerRegistry, arb.eventBus)
+	arb.prReviews = newPRReviews(arb, db, cfg.Review)
+	arb.evaluation = newEvaluation(arb, db, cfg.Evaluation)
+	arb.benchmarks = newBenchmarks(db, arb.providerRegistry, cfg.Benchmark)
+	arb.verifier = newVerifier(arb, cfg)
+	arb.ciStatus = newCIStatus(arb, db, cfg)
+	arb.issueSync = newIssueSync(arb, db, cfg)
+	arb.search = newSearch(arb, db)
+	arb.projectTemplates = newProjectTemplates(db)
+	arb.personaManager.SetStore(newPersonaStore(db))
+	arb.reportScheduler = newReportScheduler(db, arb.projectManager, arb.beadsManager, arb.goldenPrompts, notificationMgr, prices)
+	registerStandupReport(arb.reportScheduler, arb, db, gitRouter)
+	arb.backups = NewBackups(db, cfg.Backup)
+	arb.activityRetention = newActivityRetention(db, cfg.Activity.Retention)
+	arb.scheduler = newScheduler(db, cfg.Scheduler)
+	arb.panels, arb.plugins = newPluginLoader(cfg.Plugins)
+	if analyticsLogger != nil {
+		analyticsLogger.SetComplianceLookup(arb.ProjectCompliance)
+		analyticsLogger.SetLiveMetrics(arb.liveMetrics)
+	}
+	arb.beadsManager.SetOrgLookup(arb.ProjectOrg)
 	agentMgr.SetActionRouter(actionRouter)
 
 	// Enable multi-turn action loop
 	agentMgr.SetActionLoopEnabled(true)
 	agentMgr.SetMaxLoopIterations(25) // Increased from 15 to give agents more room for complex tasks
+	agentMgr.SetMaxLoopResumes(cfg.Dispatch.MaxResumes)
+	agentMgr.SetContextBudget(arb.newContextBudget(cfg.Dispatch.ContextBudget))
+	arb.chats = newChatManager(cfg.Chat)
 	if db != nil {
 		agentMgr.SetDatabase(db)
 		lessonsProvider := dispatch.NewLessonsProvider(db)
 		if lessonsProvider != nil {
+			arb.embeddingCache = memory.NewEmbeddingCache(db, cfg.Embedding.CacheSize)
+			arb.reembedder = newReembedder(db, cfg.Embedding, arb.embeddingCache)
+			lessonsProvider.SetReembedder(arb.reembedder)
+			arb.knowledge = newKnowledgeGraph(arb, db, cfg.Knowledge)
+			lessonsProvider.SetKnowledgeGraph(arb.knowledge)
+			lessonsProvider.SetContradictionDetector(newContradictionDetector(arb, db, cfg.Lessons))
+			lessonsProvider.SetSharedLessonScope(arb.sharedLessonOrg)
+			db.SetLessonSearchConfig(lessonSearchConfig(cfg.Lessons.Search))
+			lessonsProvider.SetLessonIndex(newLessonIndex(db, cfg.Lessons.Index))
 			agentMgr.SetLessonsProvider(lessonsProvider)
+			arb.lessonsProvider = lessonsProvider
 		}
+		if fileExpertise := dispatch.NewFileExpertiseProvider(db); fileExpertise != nil {
+			agentMgr.SetFileExpertiseProvider(fileExpertise)
+			arb.fileExpertise = fileExpertise
+		}
+		arb.artifactRecorder = artifacts.NewRecorder(db)
+		agentMgr.SetArtifactRecorder(arb.artifactRecorder)
+		agentMgr.SetGuidanceProvider(&beadGuidance{loom: arb})
+	}
+	if !cfg.Dispatch.ResponseRepair.Disabled {
+		agentMgr.SetResponseRepairer(&responseRepairer{registry: providerRegistry, providerID: cfg.Dispatch.ResponseRepair.Provider, serves: arb.providerServesProject})
+	}
+	if !cfg.Dispatch.ModelSwitching.Disabled {
+		agentMgr.SetModelSwitcher(&modelSwitcher{loom: arb}, cfg.Dispatch.ModelSwitching.DowngradeAfter)
 	}
 
 	arb.dispatcher = dispatch.NewDispatcher(arb.beadsManager, arb.projectManager, arb.agentManager, arb.providerRegistry, eb)
@@ -333,6 +528,15 @@ func New(cfg *config.Config) (*Loom, error) {
 	arb.dispatcher.SetReadinessMode(dispatch.ReadinessMode(cfg.Readiness.Mode))
 	arb.dispatcher.SetMaxDispatchHops(cfg.Dispatch.MaxHops)
 	arb.dispatcher.SetEscalator(arb)
+	arb.dispatcher.SetPolicyGate(policyGate)
+	arb.dispatcher.SetPlanApprover(policyGate)
+	arb.costEstimator = newCostEstimator(db, arb.providerRegistry, cfg.Dispatch.CostEstimate)
+	arb.dispatcher.SetCostEstimator(&dispatchCosts{estimator: arb.costEstimator, gate: policyGate})
+	arb.dispatcher.SetPersonas(arb.personaManager)
+	arb.dispatcher.SetOutputJudge(arb)
+	arb.dispatcher.SetFileExpertise(arb.fileExpertise)
+	arb.dispatcher.SetArtifactRecorder(arb.artifactRecorder)
+	arb.dispatcher.SetMetrics(arb.metrics)
 	// Enable conversation context support for multi-turn conversations
 	if db != nil {
 		arb.dispatcher.SetDatabase(db)
@@ -340,21 +544,71 @@ func New(cfg *config.Config) (*Loom, error) {
 
 	// Setup provider metrics tracking
 	arb.setupProviderMetrics()
+	if cfg.Models.Scoring.HalfLife > 0 {
+		arb.providerRegistry.GetScorer().SetHalfLife(cfg.Models.Scoring.HalfLife)
+	}
+	arb.providerRegistry.SetQueueConfig(providerQueueConfig(cfg.Models.Queue))
+	arb.providerRegistry.SetSaturationConfig(providerSaturationConfig(cfg.Models.Saturation))
+	arb.setupQueueMetrics()
+	arb.registerReloadHooks()
 
 	return arb, nil
 }
 
+// setupQueueMetrics exports the number of ready beads per project
+func (a *Loom) setupQueueMetrics() {
+	if a.metrics == nil || a.beadsManager == nil {
+		return
+	}
+	a.metrics.SetQueueDepthSource(func() map[string]int {
+		ready, err := a.beadsManager.GetReadyBeads("")
+		if err != nil {
+			return nil
+		}
+		depth := make(map[string]int)
+		for _, b := range ready {
+			if b != nil {
+				depth[b.ProjectID]++
+			}
+		}
+		return depth
+	})
+}
+
+// providerQueueConfig converts the configured provider request queues
+func providerQueueConfig(cfg config.QueueConfig) provider.QueueConfig {
+	return provider.QueueConfig{
+		MaxConcurrent: cfg.MaxConcurrent,
+		MaxDepth:      cfg.MaxDepth,
+		Providers:     cfg.Providers,
+	}
+}
+
+// providerSaturationConfig converts the configured saturation thresholds
+func providerSaturationConfig(cfg config.SaturationConfig) provider.SaturationConfig {
+	return provider.SaturationConfig{
+		MaxWaiting:    cfg.MaxWaiting,
+		MaxCacheUsage: cfg.MaxCacheUsage,
+	}
+}
+
 // setupProviderMetrics sets up metrics tracking callback for provider requests
 func (a *Loom) setupProviderMetrics() {
 	if a.metrics == nil || a.providerRegistry == nil {
 		return
 	}
 
+	// Record how long requests waited for saturated providers
+	a.providerRegistry.SetQueueCallback(func(providerID string, priority provider.Priority, wait time.Duration, shed bool) {
+		a.metrics.RecordProviderQueue(providerID, priority.String(), wait, shed)
+	})
+
 	// Set metrics callback to record provider requests
 	a.providerRegistry.SetMetricsCallback(func(providerID string, success bool, latencyMs int64, totalTokens int64) {
 		// Update provider metrics
 		if a.metrics != nil {
 			a.metrics.RecordProviderRequest(providerID, "", success, latencyMs, totalTokens)
+			a.metrics.RecordProviderCost(providerID, "", "", a.providerRegistry.EstimateCost(providerID, "", int(totalTokens), 0))
 		}
 
 		// Also update provider model metrics if available
@@ -676,6 +930,9 @@ func (a *Loom) Initialize(ctx context.Context) error {
 				a.beadsManager.SetProjectPrefix(p.ID, p.BeadPrefix)
 			}
 			_ = a.beadsManager.LoadBeadsFromFilesystem(p.ID, beadsPath)
+			if needsClone {
+				a.startOnboardingScan(p.ID)
+			}
 
 			// Start per-project Dolt instance if using dolt backend
 			if a.doltCoordinator != nil {
@@ -736,28 +993,12 @@ func (a *Loom) Initialize(ctx context.Context) error {
 		}
 		if len(providers) == 0 && len(a.config.Providers) > 0 {
 			for _, cfgProvider := range a.config.Providers {
-				if !cfgProvider.Enabled {
+				seed := seedProvider(cfgProvider)
+				if seed == nil {
 					continue
 				}
-				providerID := cfgProvider.ID
-				if providerID == "" && cfgProvider.Name != "" {
-					providerID = strings.ReplaceAll(strings.ToLower(cfgProvider.Name), " ", "-")
-				}
-				if providerID == "" {
-					log.Printf("Skipping provider seed without id or name: endpoint=%s", cfgProvider.Endpoint)
-					continue
-				}
-				seed := &internalmodels.Provider{
-					ID:          providerID,
-					Name:        cfgProvider.Name,
-					Type:        cfgProvider.Type,
-					Endpoint:    cfgProvider.Endpoint,
-					Model:       cfgProvider.Model,
-					RequiresKey: cfgProvider.APIKey != "",
-					Status:      "pending",
-				}
 				if _, regErr := a.RegisterProvider(ctx, seed); regErr != nil {
-					log.Printf("Failed to seed provider %s: %v", providerID, regErr)
+					log.Printf("Failed to seed provider %s: %v", seed.ID, regErr)
 				}
 			}
 			providers, err = a.database.ListProviders()
@@ -777,7 +1018,7 @@ func (a *Loom) Initialize(ctx context.Context) error {
 				ID:                     p.ID,
 				Name:                   p.Name,
 				Type:                   p.Type,
-				Endpoint:               normalizeProviderEndpoint(p.Endpoint),
+				Endpoint:               registryEndpoint(p.Type, p.Endpoint),
 				APIKey:                 "",
 				Model:                  selected,
 				ConfiguredModel:        p.ConfiguredModel,
@@ -786,8 +1027,11 @@ func (a *Loom) Initialize(ctx context.Context) error {
 				Status:                 p.Status,
 				LastHeartbeatAt:        p.LastHeartbeatAt,
 				LastHeartbeatLatencyMs: p.LastHeartbeatLatencyMs,
+				Tags:                   p.Tags,
+				OrgID:                  p.OrgID,
 			})
 		}
+		a.restoreProviderMetrics()
 
 		// Count providers ready for dispatch
 		healthyCount := 0
@@ -875,9 +1119,13 @@ func (a *Loom) Initialize(ctx context.Context) error {
 		}
 
 		// Start the Ralph Loop (10 second interval) — drains all dispatchable work per beat
-		_ = a.temporalManager.StartLoomHeartbeatWorkflow(ctx, 10*time.Second)
+		_ = a.temporalManager.StartLoomHeartbeatWorkflow(ctx, loomHeartbeatInterval)
 		// Start provider heartbeats (monitor provider health)
 		_ = a.startProviderHeartbeats(ctx)
+	} else if a.scheduler != nil {
+		// The embedded scheduler runs the same heartbeats on database timers
+		a.startScheduler(ctx)
+		_ = a.startProviderHeartbeats(ctx)
 	}
 
 	// Kick-start work on all open beads across registered projects.
@@ -905,6 +1153,51 @@ func (a *Loom) Initialize(ctx context.Context) error {
 		log.Printf("[Loom] Warning: Motivation engine not initialized")
 	}
 
+	// Keep model prices current with the dataset
+	a.pricing.Start(ctx)
+
+	// Deliver scheduled reports
+	a.reportScheduler.Start(ctx)
+
+	// Take scheduled backups
+	a.backups.Start(ctx)
+
+	// Benchmark providers on the golden tasks
+	a.benchmarks.Start(ctx)
+
+	// Wake snoozed notifications and escalate unacknowledged ones; New has
+	// already applied the escalation config
+	a.notificationManager.Start(ctx)
+
+	// Check recorded lessons for contradictions
+	a.lessonsProvider.Start(ctx)
+
+	// Archive and compact expired activity
+	a.activityRetention.Start(ctx)
+	a.reembedder.Start(ctx)
+	a.knowledge.Start(ctx)
+
+	// Poll CI on bead branches
+	a.ciStatus.Start(ctx)
+
+	// Import issues from the projects' trackers
+	a.issueSync.Start(ctx)
+
+	// File beads for outdated dependencies
+	go a.runDependencyUpdates(ctx)
+
+	// Keep the provider scorer's metrics across restarts
+	go a.runProviderMetricsPersistence(ctx)
+
+	// Stop language servers projects no longer use
+	go a.languageServers.Run(ctx)
+
+	// Index beads for search as they change
+	a.search.Start(ctx)
+
+	// Load plugins and their dashboard panels
+	a.loadPlugins(ctx)
+
 	// FIX #4: Ensure at least one project has beads for work to flow
 	// If no beads exist across all projects, create a diagnostic bead
 	hasBeads := false
@@ -1036,6 +1329,20 @@ func (a *Loom) Shutdown() {
 	if a.doltCoordinator != nil {
 		a.doltCoordinator.Shutdown()
 	}
+	a.eventWebhooks.Close()
+	a.reportScheduler.Close()
+	a.pricing.Close()
+	a.backups.Close()
+	a.activityRetention.Close()
+	a.reembedder.Close()
+	a.knowledge.Close()
+	a.ciStatus.Close()
+	a.issueSync.Close()
+	a.closeSearch()
+	a.goldenPrompts.Close()
+	a.benchmarks.Close()
+	a.scheduler.Close()
+	a.unloadPlugins()
 	if a.temporalManager != nil {
 		a.temporalManager.Stop()
 	}
@@ -1046,6 +1353,7 @@ func (a *Loom) Shutdown() {
 		}
 	}
 	if a.database != nil {
+		a.saveProviderMetrics(time.Now())
 		_ = a.database.Close()
 	}
 }
@@ -1069,6 +1377,7 @@ func (a *Loom) ExecuteShellCommand(ctx context.Context, req executor.ExecuteComm
 	if a.shellExecutor == nil {
 		return nil, fmt.Errorf("shell executor not available (database not configured)")
 	}
+	req.WorkingDir = a.commandWorkDir(req.ProjectID, req.WorkingDir)
 	return a.shellExecutor.ExecuteCommand(ctx, req)
 }
 
@@ -1136,6 +1445,9 @@ func (a *Loom) SetKeyManager(km *keymanager.KeyManager) {
 	if a.gitopsManager != nil {
 		a.gitopsManager.SetKeyManager(km)
 	}
+	if km != nil {
+		a.backups.SetKeystore(km)
+	}
 }
 
 // GetKeyManager returns the key manager
@@ -1147,6 +1459,22 @@ func (a *Loom) GetDispatcher() *dispatch.Dispatcher {
 	return a.dispatcher
 }
 
+// ProjectCompliance returns a project's compliance constraints, or nil if
+// the project is unknown or unconstrained
+func (a *Loom) ProjectCompliance(projectID string) *models.ComplianceConstraints {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil || !p.Compliance.Enabled() {
+		return nil
+	}
+	return p.Compliance
+}
+
+// GetArtifactRecorder returns the run artifact recorder, or nil when there
+// is no database to keep artifacts in
+func (a *Loom) GetArtifactRecorder() *artifacts.Recorder {
+	return a.artifactRecorder
+}
+
 // GetProjectManager returns the project manager
 func (a *Loom) GetProjectManager() *project.Manager {
 	return a.projectManager
@@ -1313,10 +1641,16 @@ func (a *Loom) GetWorkerManager() *agent.WorkerManager {
 // Project management helpers
 
 func (a *Loom) CreateProject(name, gitRepo, branch, beadsPath string, ctxMap map[string]string) (*models.Project, error) {
+	return a.CreateProjectInOrg(org.DefaultID, name, gitRepo, branch, beadsPath, ctxMap)
+}
+
+// CreateProjectInOrg creates a project owned by an organization
+func (a *Loom) CreateProjectInOrg(orgID, name, gitRepo, branch, beadsPath string, ctxMap map[string]string) (*models.Project, error) {
 	p, err := a.projectManager.CreateProject(name, gitRepo, branch, beadsPath, ctxMap)
 	if err != nil {
 		return nil, err
 	}
+	p.OrgID = org.Normalize(orgID)
 	p.BeadsPath = normalizeBeadsPath(p.BeadsPath)
 	p.GitAuthMethod = normalizeGitAuthMethod(p.GitRepo, p.GitAuthMethod)
 	_ = a.ensureDefaultAgents(context.Background(), p.ID)
@@ -1331,6 +1665,7 @@ func (a *Loom) CreateProject(name, gitRepo, branch, beadsPath string, ctxMap map
 			Data: map[string]interface{}{
 				"project_id": p.ID,
 				"name":       p.Name,
+				"org_id":     p.OrgID,
 			},
 		})
 	}
@@ -1785,6 +2120,8 @@ func (a *Loom) PersistProject(projectID string) {
 }
 
 func (a *Loom) DeleteProject(projectID string) error {
+	// Resolved first: once the project is gone its activity cannot be placed
+	orgID := a.ProjectOrg(projectID)
 	if err := a.projectManager.DeleteProject(projectID); err != nil {
 		return err
 	}
@@ -1798,6 +2135,7 @@ func (a *Loom) DeleteProject(projectID string) error {
 			ProjectID: projectID,
 			Data: map[string]interface{}{
 				"project_id": projectID,
+				"org_id":     orgID,
 			},
 		})
 	}
@@ -1940,7 +2278,7 @@ func (a *Loom) RegisterProvider(ctx context.Context, p *internalmodels.Provider,
 	}
 	// Endpoint is bootstrapped via heartbeats (port/protocol discovery), but keep the existing
 	// OpenAI default normalization for compatibility.
-	if p.Type != "ollama" {
+	if !provider.HasNativeEndpoint(p.Type) {
 		p.Endpoint = normalizeProviderEndpoint(p.Endpoint)
 	}
 	p.LastHeartbeatError = ""
@@ -1977,6 +2315,8 @@ func (a *Loom) RegisterProvider(ctx context.Context, p *internalmodels.Provider,
 		Status:                 p.Status,
 		LastHeartbeatAt:        p.LastHeartbeatAt,
 		LastHeartbeatLatencyMs: p.LastHeartbeatLatencyMs,
+		Tags:                   p.Tags,
+		OrgID:                  p.OrgID,
 	})
 	if a.eventBus != nil {
 		_ = a.eventBus.Publish(&eventbus.Event{
@@ -2019,7 +2359,7 @@ func (a *Loom) UpdateProvider(ctx context.Context, p *internalmodels.Provider) (
 	if p.Status == "" {
 		p.Status = "pending"
 	}
-	if p.Type != "ollama" {
+	if !provider.HasNativeEndpoint(p.Type) {
 		p.Endpoint = normalizeProviderEndpoint(p.Endpoint)
 	}
 	// If the operator edits a provider, we treat it as needing re-validation.
@@ -2051,6 +2391,8 @@ func (a *Loom) UpdateProvider(ctx context.Context, p *internalmodels.Provider) (
 		Status:                 p.Status,
 		LastHeartbeatAt:        p.LastHeartbeatAt,
 		LastHeartbeatLatencyMs: p.LastHeartbeatLatencyMs,
+		Tags:                   p.Tags,
+		OrgID:                  p.OrgID,
 	})
 	if a.eventBus != nil {
 		_ = a.eventBus.Publish(&eventbus.Event{
@@ -2075,7 +2417,9 @@ func (a *Loom) DeleteProvider(ctx context.Context, providerID string) error {
 		return fmt.Errorf("database not configured")
 	}
 	_ = a.providerRegistry.Unregister(providerID)
+	_ = a.scheduler.Cancel(providerHeartbeatTimer(providerID))
 	err := a.database.DeleteProvider(providerID)
+	_ = a.database.DeleteProviderMetrics(providerID)
 	if a.eventBus != nil {
 		_ = a.eventBus.Publish(&eventbus.Event{
 			Type:   eventbus.EventTypeProviderDeleted,
@@ -2109,7 +2453,7 @@ func (a *Loom) RunReplQuery(ctx context.Context, message string) (*ReplResult, e
 	if strings.TrimSpace(message) == "" {
 		return nil, fmt.Errorf("message is required")
 	}
-	if a.temporalManager == nil {
+	if a.temporalManager == nil && a.scheduler == nil {
 		return nil, fmt.Errorf("temporal manager not configured")
 	}
 	if a.database == nil {
@@ -2171,7 +2515,7 @@ func (a *Loom) RunReplQuery(ctx context.Context, message string) (*ReplResult, e
 		MaxTokens:    1200,
 	}
 
-	result, err := a.temporalManager.RunProviderQueryWorkflow(ctx, input)
+	result, err := a.runProviderQuery(ctx, input)
 	if err != nil {
 		// Update bead with error if it was created
 		if beadID != "" {
@@ -2279,6 +2623,7 @@ func (a *Loom) selectBestProviderForRepl() (*internalmodels.Provider, error) {
 	if err != nil {
 		return nil, err
 	}
+	a.applyModelMetadata(providers)
 
 	// Filter for chat-capable models (Instruct, Chat, claude, gpt, etc.)
 	// Exclude completion-only models like StarCoder, CodeGen, etc.
@@ -2361,6 +2706,7 @@ func (a *Loom) SelectProvider(ctx context.Context, requirements *routing.Provide
 	if err != nil {
 		return nil, err
 	}
+	a.applyModelMetadata(providers)
 
 	// Default policy
 	routingPolicy := routing.PolicyBalanced
@@ -2394,7 +2740,7 @@ func (a *Loom) buildLoomPersonaPrompt() string {
 }
 
 func (a *Loom) startProviderHeartbeats(ctx context.Context) error {
-	if a.temporalManager == nil || a.database == nil {
+	if (a.temporalManager == nil && a.scheduler == nil) || a.database == nil {
 		return nil
 	}
 	providers, err := a.database.ListProviders()
@@ -2411,10 +2757,17 @@ func (a *Loom) startProviderHeartbeats(ctx context.Context) error {
 }
 
 func (a *Loom) ensureProviderHeartbeat(ctx context.Context, providerID string) error {
-	if a.temporalManager == nil || providerID == "" {
+	if providerID == "" {
 		return nil
 	}
-	return a.temporalManager.StartProviderHeartbeatWorkflow(ctx, providerID, 30*time.Second)
+	if a.scheduler != nil {
+		input := temporalactivities.ProviderHeartbeatInput{ProviderID: providerID}
+		return a.scheduler.Schedule(providerHeartbeatTimer(providerID), "ProviderHeartbeatActivity", input, providerHeartbeatInterval, 0)
+	}
+	if a.temporalManager == nil {
+		return nil
+	}
+	return a.temporalManager.StartProviderHeartbeatWorkflow(ctx, providerID, providerHeartbeatInterval)
 }
 
 // NegotiateProviderModel selects the best available model from the catalog for a provider.
@@ -2491,6 +2844,8 @@ func (a *Loom) NegotiateProviderModel(ctx context.Context, providerID string) (*
 		SelectedModel:   providerRecord.SelectedModel,
 		SelectedGPU:     providerRecord.SelectedGPU,
 		Status:          "active",
+		Tags:            providerRecord.Tags,
+		OrgID:           providerRecord.OrgID,
 	})
 	if a.eventBus != nil {
 		_ = a.eventBus.Publish(&eventbus.Event{
@@ -2529,6 +2884,15 @@ func normalizeProviderEndpoint(endpoint string) string {
 	return fmt.Sprintf("%s/v1", strings.TrimSuffix(endpoint, "/"))
 }
 
+// registryEndpoint returns the endpoint a stored provider is registered
+// with: cloud providers' as configured, the others' normalized
+func registryEndpoint(providerType, endpoint string) string {
+	if provider.IsCloudType(providerType) {
+		return endpoint
+	}
+	return normalizeProviderEndpoint(endpoint)
+}
+
 // RequestFileAccess handles file lock requests from agents
 func (a *Loom) RequestFileAccess(projectID, filePath, agentID, beadID string) (*models.FileLock, error) {
 	// Verify agent exists
@@ -2649,13 +3013,28 @@ func (a *Loom) CreateBead(title, description string, priority models.BeadPriorit
 	return bead, nil
 }
 
-// CloseBead closes a bead with an optional reason
+// CloseBead closes a bead with an optional reason. With verification
+// enabled for the bead, its project's tests must pass first; a failure keeps
+// the bead open.
 func (a *Loom) CloseBead(beadID, reason string) error {
 	bead, err := a.beadsManager.GetBead(beadID)
 	if err != nil {
 		return fmt.Errorf("bead not found: %w", err)
 	}
 
+	if a.verifier.Enabled(bead.ProjectID, bead.Type) {
+		res, err := a.VerifyBead(context.Background(), beadID)
+		if err != nil {
+			return fmt.Errorf("failed to verify bead: %w", err)
+		}
+		if !res.Passed {
+			return fmt.Errorf("bead %s failed verification and stays open: %s", beadID, res.Summary())
+		}
+		if bead, err = a.beadsManager.GetBead(beadID); err != nil {
+			return fmt.Errorf("bead not found: %w", err)
+		}
+	}
+
 	updates := map[string]interface{}{
 		"status": models.BeadStatusClosed,
 	}
@@ -2885,6 +3264,7 @@ func (a *Loom) MakeDecision(decisionID, deciderID, decisionText, rationale strin
 	if err := a.decisionManager.MakeDecision(decisionID, deciderID, decisionText, rationale); err != nil {
 		return fmt.Errorf("failed to make decision: %w", err)
 	}
+	a.auditDecision(decisionID, deciderID, decisionText, rationale)
 
 	// Unblock dependent beads
 	if err := a.UnblockDependents(decisionID); err != nil {
@@ -2907,6 +3287,9 @@ func (a *Loom) MakeDecision(decisionID, deciderID, decisionText, rationale strin
 	}
 
 	_ = a.applyCEODecisionToParent(decisionID)
+	if d, err := a.decisionManager.GetDecision(decisionID); err == nil && d != nil {
+		a.applyPatchDecision(d)
+	}
 
 	return nil
 }
@@ -3158,6 +3541,14 @@ func (a *Loom) StartMaintenanceLoop(ctx context.Context) {
 				log.Printf("[Maintenance] Reset %d stuck agents", resetCount)
 			}
 
+			// Warn about configured models approaching end-of-life
+			a.checkModelDeprecations()
+
+			// Retire provider keys whose rotation grace period has elapsed
+			if retired := a.RetireExpiredCredentials(); retired > 0 {
+				log.Printf("[Maintenance] Retired %d rotated provider keys", retired)
+			}
+
 			// NOTE: Stuck bead resolution is handled by the Ralph Loop
 			// (LoomHeartbeatActivity). CEO escalation is only available via
 			// explicit CLI/REPL commands.
@@ -3256,6 +3647,8 @@ func (a *Loom) checkProviderHealthAndActivate(providerID string) {
 			Status:                 "active",
 			LastHeartbeatAt:        dbProvider.LastHeartbeatAt,
 			LastHeartbeatLatencyMs: dbProvider.LastHeartbeatLatencyMs,
+			Tags:                   dbProvider.Tags,
+			OrgID:                  dbProvider.OrgID,
 		})
 		log.Printf("Provider %s activated successfully", providerID)
 	}
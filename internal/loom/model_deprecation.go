@@ -0,0 +1,315 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/modelcatalog"
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+const (
+	deprecationWarnInterval = 24 * time.Hour
+
+	defaultMigrationSamples = 5
+	maxMigrationSamples     = 20
+	migrationPromptTimeout  = 2 * time.Minute
+)
+
+// ProviderModelDeprecation is a deprecation notice for a provider's
+// configured model
+type ProviderModelDeprecation struct {
+	ProviderID   string `json:"provider_id"`
+	ProviderName string `json:"provider_name"`
+	*modelcatalog.DeprecationNotice
+}
+
+// ModelMigrationRequest configures a migration assistant run
+type ModelMigrationRequest struct {
+	ProviderID       string   `json:"provider_id"`
+	ReplacementModel string   `json:"replacement_model,omitempty"` // Defaults to the catalog replacement
+	SampleSize       int      `json:"sample_size,omitempty"`
+	Prompts          []string `json:"prompts,omitempty"` // Used instead of recorded prompts when set
+}
+
+// deprecationWarningWindow returns how far ahead of end-of-life models are flagged
+func (a *Loom) deprecationWarningWindow() time.Duration {
+	if a.config != nil && a.config.Models.DeprecationWarningDays > 0 {
+		return time.Duration(a.config.Models.DeprecationWarningDays) * 24 * time.Hour
+	}
+	return modelcatalog.DefaultDeprecationWarning
+}
+
+// ListModelDeprecations returns deprecation notices for every provider whose
+// selected model is deprecated, retiring, or retired
+func (a *Loom) ListModelDeprecations() ([]ProviderModelDeprecation, error) {
+	providers, err := a.ListProviders()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	window := a.deprecationWarningWindow()
+	notices := []ProviderModelDeprecation{}
+	for _, p := range providers {
+		model := p.SelectedModel
+		if model == "" {
+			model = p.Model
+		}
+		if notice := a.modelCatalog.CheckDeprecation(model, now, window); notice != nil {
+			notices = append(notices, ProviderModelDeprecation{
+				ProviderID:        p.ID,
+				ProviderName:      p.Name,
+				DeprecationNotice: notice,
+			})
+		}
+	}
+	return notices, nil
+}
+
+// checkModelDeprecations logs a warning for models nearing end-of-life, at
+// most once a day per provider and model
+func (a *Loom) checkModelDeprecations() {
+	if a.database == nil || a.modelCatalog == nil {
+		return
+	}
+	notices, err := a.ListModelDeprecations()
+	if err != nil {
+		log.Printf("[ModelCatalog] Failed to check model deprecations: %v", err)
+		return
+	}
+
+	a.deprecationMu.Lock()
+	defer a.deprecationMu.Unlock()
+	if a.deprecationWarnedAt == nil {
+		a.deprecationWarnedAt = make(map[string]time.Time)
+	}
+
+	for _, n := range notices {
+		if n.Status == modelcatalog.DeprecationStatusDeprecated {
+			continue
+		}
+		key := n.ProviderID + "|" + n.Model
+		if last, ok := a.deprecationWarnedAt[key]; ok && time.Since(last) < deprecationWarnInterval {
+			continue
+		}
+		a.deprecationWarnedAt[key] = time.Now()
+
+		log.Printf("[ModelCatalog] Provider %s model %s is %s (end of life %s, %d days left); replacement: %s",
+			n.ProviderID, n.Model, n.Status, n.EndOfLife, n.DaysRemaining, n.Replacement)
+		observability.Info("model.deprecation_warning", map[string]interface{}{
+			"provider_id":    n.ProviderID,
+			"model":          n.Model,
+			"status":         n.Status,
+			"end_of_life":    n.EndOfLife,
+			"days_remaining": n.DaysRemaining,
+			"replacement":    n.Replacement,
+		})
+	}
+}
+
+// RunModelMigration re-runs a sample of recent prompts against the
+// replacement model and reports quality and cost deltas against the
+// provider's current model
+func (a *Loom) RunModelMigration(ctx context.Context, req ModelMigrationRequest) (*modelcatalog.MigrationReport, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	p, err := a.database.GetProvider(req.ProviderID)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, fmt.Errorf("provider %s not found", req.ProviderID)
+	}
+
+	current := p.SelectedModel
+	if current == "" {
+		current = p.Model
+	}
+	notice := a.modelCatalog.CheckDeprecation(current, time.Now(), a.deprecationWarningWindow())
+	replacement := req.ReplacementModel
+	if replacement == "" && notice != nil {
+		replacement = notice.Replacement
+	}
+	if replacement == "" {
+		return nil, fmt.Errorf("no replacement model known for %s; specify replacement_model", current)
+	}
+
+	sampleSize := req.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultMigrationSamples
+	}
+	if sampleSize > maxMigrationSamples {
+		sampleSize = maxMigrationSamples
+	}
+
+	prompts, err := a.migrationPrompts(ctx, req, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &modelcatalog.MigrationReport{
+		ProviderID:       p.ID,
+		CurrentModel:     current,
+		ReplacementModel: replacement,
+		CandidateTarget:  a.providerServingModel(replacement, p.ID),
+		Deprecation:      notice,
+	}
+	for _, mp := range prompts {
+		sample := modelcatalog.MigrationSample{
+			Source: mp.source,
+			Prompt: truncateForReport(lastUserContent(mp.messages), 500),
+		}
+		sample.Baseline = a.runMigrationPrompt(ctx, p.ID, current, mp.messages)
+		sample.Candidate = a.runMigrationPrompt(ctx, report.CandidateTarget, replacement, mp.messages)
+		report.Samples = append(report.Samples, sample)
+	}
+	report.Summarize()
+
+	observability.Info("model.migration_report", map[string]interface{}{
+		"provider_id":       p.ID,
+		"current_model":     current,
+		"replacement_model": replacement,
+		"compared":          report.Compared,
+		"avg_similarity":    report.AvgSimilarity,
+		"cost_delta_pct":    report.CostDeltaPct,
+		"recommendation":    report.Recommendation,
+	})
+	return report, nil
+}
+
+type migrationPrompt struct {
+	source   string
+	messages []provider.ChatMessage
+}
+
+// migrationPrompts returns the prompts to replay: the caller's prompts if
+// given, otherwise the most recent recorded requests for the provider
+func (a *Loom) migrationPrompts(ctx context.Context, req ModelMigrationRequest, limit int) ([]migrationPrompt, error) {
+	var prompts []migrationPrompt
+	for _, text := range req.Prompts {
+		if len(prompts) >= limit {
+			break
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		prompts = append(prompts, migrationPrompt{
+			source:   "provided",
+			messages: []provider.ChatMessage{{Role: "user", Content: text}},
+		})
+	}
+	if len(req.Prompts) > 0 {
+		return prompts, nil
+	}
+
+	storage, err := analytics.NewDatabaseStorage(a.database.DB())
+	if err != nil {
+		return nil, err
+	}
+	// Only requests logged with bodies can be replayed, so scan past the sample size
+	logs, err := storage.GetLogs(ctx, &analytics.LogFilter{ProviderID: req.ProviderID, Limit: limit * 10})
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range logs {
+		if len(prompts) >= limit {
+			break
+		}
+		if messages := parseLoggedMessages(l.RequestBody); len(messages) > 0 {
+			prompts = append(prompts, migrationPrompt{source: l.ID, messages: messages})
+		}
+	}
+	return prompts, nil
+}
+
+// runMigrationPrompt sends one prompt to a model and records the outcome
+func (a *Loom) runMigrationPrompt(ctx context.Context, providerID, model string, messages []provider.ChatMessage) modelcatalog.MigrationRun {
+	run := modelcatalog.MigrationRun{Model: model}
+
+	reqCtx, cancel := context.WithTimeout(provider.WithPriority(ctx, provider.PriorityP3), migrationPromptTimeout)
+	defer cancel()
+
+	msgs := make([]provider.ChatMessage, len(messages))
+	copy(msgs, messages)
+	start := time.Now()
+	resp, err := a.providerRegistry.SendChatCompletion(reqCtx, providerID, &provider.ChatCompletionRequest{
+		Model:    model,
+		Messages: msgs,
+	})
+	run.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		run.Error = err.Error()
+		return run
+	}
+	if resp.Model != "" && !strings.EqualFold(resp.Model, model) {
+		// The provider substituted another model; the comparison would be meaningless
+		run.Error = fmt.Sprintf("provider served %s instead of %s", resp.Model, model)
+		return run
+	}
+	if len(resp.Choices) > 0 {
+		run.Response = resp.Choices[0].Message.Content
+	}
+	run.PromptTokens = resp.Usage.PromptTokens
+	run.CompletionTokens = resp.Usage.CompletionTokens
+	run.ValidJSON = modelcatalog.IsJSONResponse(run.Response)
+	run.CostUSD = a.providerRegistry.EstimateCost(providerID, model, resp.Usage.PromptTokens, resp.Usage.CompletionTokens)
+	return run
+}
+
+// providerServingModel prefers a registered provider already serving model,
+// falling back to asking the current provider for it by name
+func (a *Loom) providerServingModel(model, fallbackID string) string {
+	for _, rp := range a.providerRegistry.ListActive() {
+		if rp.Config != nil && strings.EqualFold(rp.Config.Model, model) {
+			return rp.Config.ID
+		}
+	}
+	return fallbackID
+}
+
+// parseLoggedMessages extracts chat messages from a logged request body in
+// any supported provider dialect
+func parseLoggedMessages(body string) []provider.ChatMessage {
+	if body == "" {
+		return nil
+	}
+	body = provider.CanonicalizeRequestBody(body)
+	var req struct {
+		Messages []provider.ChatMessage `json:"messages"`
+		Prompt   string                 `json:"prompt"`
+	}
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		return nil
+	}
+	if len(req.Messages) > 0 {
+		return req.Messages
+	}
+	if req.Prompt != "" {
+		return []provider.ChatMessage{{Role: "user", Content: req.Prompt}}
+	}
+	return nil
+}
+
+func lastUserContent(messages []provider.ChatMessage) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func truncateForReport(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max] + "..."
+}
@@ -0,0 +1,65 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/modelcatalog"
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestRunModelMigration(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	defer os.RemoveAll(tmpDir)
+
+	p := &internalmodels.Provider{
+		ID:            "mock-1",
+		Name:          "mock-1",
+		Type:          "mock",
+		Status:        "active",
+		SelectedModel: "Qwen2.5-Coder-7B-Instruct",
+	}
+	if err := l.GetDatabase().UpsertProvider(p); err != nil {
+		t.Fatalf("UpsertProvider failed: %v", err)
+	}
+	if err := l.GetProviderRegistry().Upsert(&provider.ProviderConfig{
+		ID: "mock-1", Name: "mock-1", Type: "mock", Status: "active",
+		Model: "Qwen2.5-Coder-7B-Instruct", CostPerMToken: 1,
+	}); err != nil {
+		t.Fatalf("registry Upsert failed: %v", err)
+	}
+
+	notices, err := l.ListModelDeprecations()
+	if err != nil {
+		t.Fatalf("ListModelDeprecations failed: %v", err)
+	}
+	if len(notices) != 1 || notices[0].ProviderID != "mock-1" {
+		t.Fatalf("expected a deprecation notice for mock-1, got %+v", notices)
+	}
+
+	report, err := l.RunModelMigration(context.Background(), ModelMigrationRequest{
+		ProviderID: "mock-1",
+		Prompts:    []string{"list the files", "", "explain main.go"},
+	})
+	if err != nil {
+		t.Fatalf("RunModelMigration failed: %v", err)
+	}
+	if report.ReplacementModel != notices[0].Replacement {
+		t.Errorf("replacement = %q, want catalog replacement %q", report.ReplacementModel, notices[0].Replacement)
+	}
+	if report.Compared != 2 {
+		t.Fatalf("compared = %d, want 2", report.Compared)
+	}
+	if report.Samples[0].Candidate.Model != report.ReplacementModel || report.Samples[0].Candidate.CostUSD == 0 {
+		t.Errorf("candidate run not recorded: %+v", report.Samples[0].Candidate)
+	}
+	if report.Recommendation != modelcatalog.MigrationRecommendMigrate {
+		t.Errorf("recommendation = %q, want migrate", report.Recommendation)
+	}
+
+	if _, err := l.RunModelMigration(context.Background(), ModelMigrationRequest{ProviderID: "missing"}); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
@@ -0,0 +1,62 @@
+package loom
+
+import (
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// modelMetadataFromConfig converts config metadata entries to catalog entries
+func modelMetadataFromConfig(entries []config.ModelMetadata) []internalmodels.ModelMetadata {
+	out := make([]internalmodels.ModelMetadata, 0, len(entries))
+	for _, m := range entries {
+		out = append(out, internalmodels.ModelMetadata{
+			Model:               m.Model,
+			ContextWindow:       m.ContextWindow,
+			MaxOutputTokens:     m.MaxOutputTokens,
+			SupportsTools:       m.SupportsTools,
+			SupportsVision:      m.SupportsVision,
+			SupportsJSONMode:    m.SupportsJSONMode,
+			SupportsJSONSchema:  m.SupportsJSONSchema,
+			InputCostPerMToken:  m.InputCostPerMToken,
+			OutputCostPerMToken: m.OutputCostPerMToken,
+			KnowledgeCutoff:     m.KnowledgeCutoff,
+		})
+	}
+	return out
+}
+
+// ListModelMetadata returns the merged metadata for every known model
+func (a *Loom) ListModelMetadata() []internalmodels.ModelMetadata {
+	return a.modelCatalog.ListModelMetadata()
+}
+
+// applyModelMetadata fills in routing fields the provider record leaves
+// unset from its model's metadata. Values stored on the provider (from
+// heartbeats or the API) take precedence.
+func (a *Loom) applyModelMetadata(providers []*internalmodels.Provider) {
+	for _, p := range providers {
+		if p == nil {
+			continue
+		}
+		model := p.SelectedModel
+		if model == "" {
+			model = p.Model
+		}
+		md, ok := a.modelCatalog.ModelMetadata(model)
+		if !ok {
+			continue
+		}
+		if p.ContextWindow == 0 {
+			p.ContextWindow = md.ContextWindow
+		}
+		if p.CostPerMToken == 0 && md.HasPricing() {
+			p.CostPerMToken = md.BlendedCostPerMToken()
+		}
+		if md.SupportsTools != nil && !p.SupportsFunction {
+			p.SupportsFunction = *md.SupportsTools
+		}
+		if md.SupportsVision != nil && !p.SupportsVision {
+			p.SupportsVision = *md.SupportsVision
+		}
+	}
+}
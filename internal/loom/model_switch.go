@@ -0,0 +1,82 @@
+package loom
+
+import (
+	"github.com/jordanhubbard/loom/internal/logging"
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/internal/worker"
+)
+
+// modelSwitcher moves action loops between model tiers on the providers a
+// project allows. A provider's tier comes from its model size; providers
+// whose size is unknown have no tier and are never switched to or from.
+type modelSwitcher struct {
+	loom *Loom
+}
+
+// NextTier returns the provider of the nearest tier above (or below)
+// current's. Going up, the most capable provider of that tier wins; going
+// down, the cheapest.
+func (s *modelSwitcher) NextTier(projectID string, current *provider.RegisteredProvider, up bool) *provider.RegisteredProvider {
+	if current == nil || current.Config == nil || current.Config.ModelParamsB <= 0 {
+		return nil
+	}
+	from := provider.GetModelTier(current.Config.ModelParamsB)
+
+	var best *provider.RegisteredProvider
+	var bestTier provider.ModelTier
+	for _, rp := range s.loom.providerRegistry.ListActive() {
+		cfg := rp.Config
+		if cfg.ID == current.Config.ID || cfg.ModelParamsB <= 0 || !s.loom.providerServesProject(cfg, projectID) {
+			continue
+		}
+		tier := provider.GetModelTier(cfg.ModelParamsB)
+		if (up && tier <= from) || (!up && tier >= from) {
+			continue
+		}
+		switch {
+		case best == nil, up && tier < bestTier, !up && tier > bestTier:
+			best, bestTier = rp, tier
+		case tier == bestTier && !up && cfg.CostPerMToken < best.Config.CostPerMToken:
+			best = rp
+		}
+	}
+	return best
+}
+
+// RecordModelSwitch puts a loop's change of model in the action log and
+// the bead's activity feed
+func (s *modelSwitcher) RecordModelSwitch(projectID, beadID, agentID string, iteration int, sw *worker.ModelSwitch) {
+	a := s.loom
+	metadata := map[string]interface{}{
+		"agent_id":      agentID,
+		"bead_id":       beadID,
+		"project_id":    projectID,
+		"iteration":     iteration,
+		"direction":     sw.Direction,
+		"reason":        sw.Reason,
+		"from_provider": sw.FromProvider,
+		"from_model":    sw.FromModel,
+		"from_tier":     sw.FromTier,
+		"to_provider":   sw.ToProvider,
+		"to_model":      sw.ToModel,
+		"to_tier":       sw.ToTier,
+	}
+	if a.logManager != nil {
+		a.logManager.Log(logging.LogLevelInfo, "actions", "model switched", metadata)
+	}
+	observability.Info("agent.model_switch", metadata)
+
+	if a.eventBus == nil || beadID == "" {
+		return
+	}
+	data := map[string]interface{}{"actor_id": agentID, "actor_type": "agent"}
+	for k, v := range metadata {
+		data[k] = v
+	}
+	if bead, err := a.beadsManager.GetBead(beadID); err == nil && bead != nil {
+		data["title"] = bead.Title
+	}
+	_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadModelSwitched, beadID, projectID, data)
+}
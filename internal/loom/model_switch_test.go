@@ -0,0 +1,47 @@
+package loom
+
+import (
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestModelSwitcher_NextTier(t *testing.T) {
+	l, _ := testLoom(t)
+	reg := l.providerRegistry
+	for _, cfg := range []*provider.ProviderConfig{
+		{ID: "small", ModelParamsB: 7},
+		{ID: "medium-costly", ModelParamsB: 30, CostPerMToken: 3},
+		{ID: "medium-cheap", ModelParamsB: 32, CostPerMToken: 1},
+		{ID: "xlarge", ModelParamsB: 400},
+		{ID: "unsized"},
+		{ID: "demo-mock", ModelParamsB: 70, Tags: []string{provider.DemoTag}},
+	} {
+		cfg.Type, cfg.Status = "mock", "active"
+		reg.UpsertProtocol(cfg, provider.NewMockProvider())
+	}
+	get := func(id string) *provider.RegisteredProvider {
+		rp, err := reg.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rp
+	}
+	s := &modelSwitcher{loom: l}
+
+	if got := s.NextTier("p1", get("small"), true); got == nil || got.Config.ModelParamsB < 10 || got.Config.ModelParamsB >= 50 {
+		t.Errorf("upgrade from small = %v, want a medium provider", got)
+	}
+	if got := s.NextTier("p1", get("medium-cheap"), true); got == nil || got.Config.ID != "xlarge" {
+		t.Errorf("upgrade from medium = %v, want xlarge past the demo provider", got)
+	}
+	if got := s.NextTier("p1", get("xlarge"), false); got == nil || got.Config.ID != "medium-cheap" {
+		t.Errorf("downgrade from xlarge = %v, want the cheapest medium provider", got)
+	}
+	if got := s.NextTier("p1", get("small"), false); got != nil {
+		t.Errorf("downgrade from small = %v, want none", got.Config.ID)
+	}
+	if got := s.NextTier("p1", get("unsized"), true); got != nil {
+		t.Errorf("upgrade from a provider of unknown size = %v, want none", got.Config.ID)
+	}
+}
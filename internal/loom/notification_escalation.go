@@ -0,0 +1,58 @@
+package loom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/notifications"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// configureEscalation sets how long notifications wait for acknowledgment
+// and sends exhausted on-call chains to the CEO decision queue
+func (a *Loom) configureEscalation(mgr *notifications.Manager, cfg config.EscalationConfig) {
+	window := cfg.AckWindow
+	if cfg.Disabled {
+		window = -1
+	}
+	mgr.SetEscalation(window, a.escalateNotificationToCEO)
+	mgr.SetProjectOrgLookup(a.ProjectOrg)
+}
+
+// escalateNotificationToCEO creates a P0 CEO decision for a notification no
+// one in its project's on-call chain acknowledged
+func (a *Loom) escalateNotificationToCEO(e *notifications.Escalation) (string, error) {
+	question := fmt.Sprintf("CEO decision required: %s notification %q in project %s was not acknowledged.\n\n%s\n\nPaged: %s\n\nChoose: acknowledge | reassign | dismiss",
+		e.Priority, e.Title, e.ProjectID, e.Message, strings.Join(e.Notified, ", "))
+	decision, err := a.decisionManager.CreateDecision(question, "", "system", []string{"acknowledge", "reassign", "dismiss"}, "", models.BeadPriorityP0, e.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	if decision.Context == nil {
+		decision.Context = make(map[string]string)
+	}
+	decision.Context["escalated_to"] = "ceo"
+	decision.Context["escalation_reason"] = "notification not acknowledged"
+	decision.Context["notification_escalation_id"] = e.ID
+	decision.Context["escalated_to_ceo_at"] = time.Now().UTC().Format(time.RFC3339)
+	if e.Link != "" {
+		decision.Context["link"] = e.Link
+	}
+
+	if a.eventBus != nil {
+		_ = a.eventBus.Publish(&eventbus.Event{
+			Type:      eventbus.EventTypeDecisionCreated,
+			Source:    "notification-escalation",
+			ProjectID: e.ProjectID,
+			Data: map[string]interface{}{
+				"decision_id":   decision.ID,
+				"escalation_id": e.ID,
+				"reason":        "notification not acknowledged",
+			},
+		})
+	}
+	return decision.ID, nil
+}
@@ -0,0 +1,122 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/linter"
+	"github.com/jordanhubbard/loom/internal/onboarding"
+)
+
+const (
+	// onboardingScanKey opts a project into the onboarding scan: set to
+	// "true", the repository is scanned once its first clone finishes and
+	// the proposed backlog is filed
+	onboardingScanKey = "onboarding_scan"
+	// onboardingScannedKey records when the backlog of a scan was filed, so
+	// a project is onboarded only once
+	onboardingScannedKey = "onboarding_scanned_at"
+	// onboardingScanTimeout bounds an automatic scan, which runs the linter
+	// and asks package managers for updates
+	onboardingScanTimeout = 20 * time.Minute
+)
+
+// ScanProjectForOnboarding scans a project's working copy for missing
+// tests, TODO and FIXME comments, outdated dependencies and lint
+// violations, and proposes a prioritized backlog of at most maxBeads beads.
+// Unless dryRun is set the beads are filed, tagged "onboarding" and their
+// kind, with their complexity estimate in their context.
+func (a *Loom) ScanProjectForOnboarding(ctx context.Context, projectID, actor string, maxBeads int, dryRun bool) (*onboarding.Report, error) {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+	if p.WorkDir == "" {
+		return nil, fmt.Errorf("project %s has no working copy to scan; clone it first", projectID)
+	}
+	// An automatic and a requested scan must not file the same backlog twice
+	if _, running := a.onboardingScans.LoadOrStore(projectID, true); running {
+		return nil, fmt.Errorf("an onboarding scan of project %s is already running", projectID)
+	}
+	defer a.onboardingScans.Delete(projectID)
+
+	scanner := onboarding.NewScanner(linter.NewLinterRunner(p.WorkDir), onboarding.CommandDependencyChecker{})
+	report, err := scanner.Scan(ctx, p.WorkDir, maxBeads)
+	if err != nil {
+		return nil, err
+	}
+	report.ProjectID = projectID
+	if dryRun {
+		return report, nil
+	}
+
+	for i := range report.Beads {
+		proposed := &report.Beads[i]
+		bead, err := a.CreateBead(proposed.Title, proposed.Description, proposed.Priority, proposed.Type, projectID)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("failed to file bead %q: %v", proposed.Title, err))
+			continue
+		}
+		if _, err := a.UpdateBead(bead.ID, map[string]interface{}{
+			"tags": []string{"onboarding", proposed.Kind},
+			"context": map[string]string{
+				"complexity":      proposed.Complexity,
+				"onboarding_kind": proposed.Kind,
+				"files":           strings.Join(proposed.Files, ","),
+			},
+		}); err != nil {
+			log.Printf("[Onboarding] Failed to tag bead %s: %v", bead.ID, err)
+		}
+		proposed.BeadID = bead.ID
+	}
+	report.Filed = true
+
+	projectContext := make(map[string]string, len(p.Context)+1)
+	for k, v := range p.Context {
+		projectContext[k] = v
+	}
+	projectContext[onboardingScannedKey] = report.ScannedAt.Format(time.RFC3339)
+	if err := a.projectManager.UpdateProject(projectID, map[string]interface{}{"context": projectContext}); err == nil {
+		a.PersistProject(projectID)
+	}
+
+	audit.Record(audit.Event{
+		Actor:     actor,
+		Action:    "project.onboarding_scan",
+		Resource:  projectID,
+		ProjectID: projectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"files":    report.Files,
+			"findings": report.Findings,
+			"beads":    len(report.Beads),
+			"omitted":  report.Omitted,
+			"warnings": report.Warnings,
+		},
+	})
+	return report, nil
+}
+
+// startOnboardingScan files the onboarding backlog of a freshly cloned
+// project in the background when the project opted in and was not scanned
+// before
+func (a *Loom) startOnboardingScan(projectID string) {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil || p.Context[onboardingScanKey] != "true" || p.Context[onboardingScannedKey] != "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), onboardingScanTimeout)
+		defer cancel()
+		report, err := a.ScanProjectForOnboarding(ctx, projectID, "system", 0, false)
+		if err != nil {
+			log.Printf("[Onboarding] Scan of project %s failed: %v", projectID, err)
+			return
+		}
+		log.Printf("[Onboarding] Filed %d beads for project %s from %d scanned files", len(report.Beads), projectID, report.Files)
+	}()
+}
@@ -0,0 +1,59 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanProjectForOnboarding(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	proj, err := l.CreateProject("onboard", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	if _, err := l.ScanProjectForOnboarding(context.Background(), proj.ID, "u-1", 0, true); err == nil {
+		t.Error("expected a scan without a working copy to fail")
+	}
+
+	workDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workDir, "main.go"), []byte("package main\n\n// FIXME exit code\nfunc main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	proj.WorkDir = workDir
+
+	preview, err := l.ScanProjectForOnboarding(context.Background(), proj.ID, "u-1", 0, true)
+	if err != nil {
+		t.Fatalf("dry run failed: %v", err)
+	}
+	if preview.Filed || len(preview.Beads) == 0 || preview.Beads[0].BeadID != "" {
+		t.Fatalf("dry run = %+v, want proposed beads left unfiled", preview)
+	}
+	if proj.Context[onboardingScannedKey] != "" {
+		t.Error("a dry run must not mark the project as onboarded")
+	}
+
+	report, err := l.ScanProjectForOnboarding(context.Background(), proj.ID, "u-1", 0, false)
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if !report.Filed || len(report.Beads) != len(preview.Beads) {
+		t.Fatalf("scan filed %d beads, want the %d proposed", len(report.Beads), len(preview.Beads))
+	}
+	for _, proposed := range report.Beads {
+		bead, err := l.beadsManager.GetBead(proposed.BeadID)
+		if err != nil {
+			t.Fatalf("filed bead %q not found: %v", proposed.Title, err)
+		}
+		if bead.ProjectID != proj.ID || len(bead.Tags) != 2 || bead.Tags[0] != "onboarding" || bead.Context["complexity"] != proposed.Complexity {
+			t.Errorf("filed bead = %+v", bead)
+		}
+	}
+	if proj.Context[onboardingScannedKey] == "" {
+		t.Error("expected the project to be marked as onboarded")
+	}
+}
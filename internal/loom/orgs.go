@@ -0,0 +1,73 @@
+package loom
+
+import (
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// newOrgManager loads the organizations kept in db. Without a database, or
+// when they cannot be read, only the default organization exists.
+func newOrgManager(db *database.Database) *org.Manager {
+	var store org.Store
+	if db != nil {
+		store = db
+	}
+	mgr, err := org.NewManager(store)
+	if err != nil {
+		log.Printf("Warning: using the default organization only: %v", err)
+		return nil
+	}
+	return mgr
+}
+
+// GetOrgManager returns the organization manager; nil means only the
+// default organization exists
+func (a *Loom) GetOrgManager() *org.Manager {
+	return a.orgManager
+}
+
+// ProjectOrg returns the organization owning a project, or "" when the
+// project is unknown
+func (a *Loom) ProjectOrg(projectID string) string {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil {
+		return ""
+	}
+	return org.Normalize(p.OrgID)
+}
+
+// providerOrg returns the organization a provider is reserved for, or ""
+// when it is shared
+func (a *Loom) providerOrg(providerID string) string {
+	p, err := a.providerRegistry.Get(providerID)
+	if err != nil || p.Config == nil {
+		return ""
+	}
+	return p.Config.OrgID
+}
+
+// activityOrg places an activity in the organization of its project, else
+// of its provider
+func (a *Loom) activityOrg(projectID, providerID string) string {
+	if projectID != "" {
+		if orgID := a.ProjectOrg(projectID); orgID != "" {
+			return orgID
+		}
+	}
+	if providerID != "" {
+		return a.providerOrg(providerID)
+	}
+	return ""
+}
+
+// ListProvidersInOrg returns the providers an organization's projects may
+// use: its own and the shared ones
+func (a *Loom) ListProvidersInOrg(orgID string) ([]*internalmodels.Provider, error) {
+	if a.database == nil {
+		return []*internalmodels.Provider{}, nil
+	}
+	return a.database.ListProvidersInOrg(orgID)
+}
@@ -0,0 +1,57 @@
+package loom
+
+import (
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/persona"
+)
+
+// newPersonaStore keeps published persona definitions in db. Without a
+// database only the SKILL.md personas are available.
+func newPersonaStore(db *database.Database) persona.Store {
+	if db == nil {
+		return nil
+	}
+	return db
+}
+
+// PublishPersona stores a new version of a persona's definition and hands
+// it to the persona's agents
+func (a *Loom) PublishPersona(name string, def persona.Definition, comment, createdBy string) (*persona.Version, error) {
+	v, err := a.personaManager.Publish(name, def, comment, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	a.refreshAgentPersonas(name)
+	return v, nil
+}
+
+// RollbackPersona makes an older version of a persona's definition active
+// again and hands it to the persona's agents
+func (a *Loom) RollbackPersona(name string, version int, comment, createdBy string) (*persona.Version, error) {
+	v, err := a.personaManager.Rollback(name, version, comment, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	a.refreshAgentPersonas(name)
+	return v, nil
+}
+
+// DeletePersona removes a persona's published versions. Agents of a persona
+// with a SKILL.md go back to it; agents of one without keep the definition
+// they had until they are restarted.
+func (a *Loom) DeletePersona(name string) error {
+	if err := a.personaManager.DeleteVersions(name); err != nil {
+		return err
+	}
+	a.refreshAgentPersonas(name)
+	return nil
+}
+
+// refreshAgentPersonas reloads a persona into the agents that use it
+func (a *Loom) refreshAgentPersonas(name string) {
+	p, err := a.personaManager.LoadPersona(name)
+	if err != nil {
+		return
+	}
+	a.agentManager.SetAgentPersona(name, p)
+}
@@ -0,0 +1,59 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/persona"
+)
+
+func TestPublishPersona_RefreshesAgentsAndLimitsActions(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	p, err := l.personaManager.LoadPersona("default/qa-engineer")
+	if err != nil {
+		t.Fatalf("LoadPersona failed: %v", err)
+	}
+	ag, err := l.agentManager.CreateAgent(context.Background(), "QA", "default/qa-engineer", "", "qa-engineer", p)
+	if err != nil {
+		t.Fatalf("CreateAgent failed: %v", err)
+	}
+	gate := newPolicyGate(l, nil)
+	actx := actions.ActionContext{AgentID: ag.ID, BeadID: "bd-1", ProjectID: "proj-1"}
+	if ok, reason, _ := gate.CheckAction(context.Background(), actions.Action{Type: actions.ActionGitPush}, actx); !ok {
+		t.Fatalf("expected an unrestricted persona to allow git_push, got %q", reason)
+	}
+
+	v, err := l.PublishPersona("default/qa-engineer", persona.Definition{
+		SystemPrompt:   "Test, never ship.",
+		AllowedActions: []string{actions.ActionReadFile, actions.ActionRunTests},
+	}, "", "admin")
+	if err != nil {
+		t.Fatalf("PublishPersona failed: %v", err)
+	}
+	if ag.Persona == nil || ag.Persona.DefinitionVersion != v.Version || ag.Persona.Mission != "Test, never ship." {
+		t.Fatalf("expected the agent to get the published definition, got %+v", ag.Persona)
+	}
+
+	ok, reason, _ := gate.CheckAction(context.Background(), actions.Action{Type: actions.ActionGitPush}, actx)
+	if ok || !strings.Contains(reason, "default/qa-engineer") {
+		t.Errorf("expected git_push to be denied by the persona, got ok=%v reason=%q", ok, reason)
+	}
+	for _, allowed := range []string{actions.ActionRunTests, actions.ActionDone} {
+		if ok, reason, _ := gate.CheckAction(context.Background(), actions.Action{Type: allowed}, actx); !ok {
+			t.Errorf("expected %s to be allowed, got %q", allowed, reason)
+		}
+	}
+
+	// Deleting the published versions hands the agent its SKILL.md again
+	if err := l.DeletePersona("default/qa-engineer"); err != nil {
+		t.Fatalf("DeletePersona failed: %v", err)
+	}
+	if ag.Persona.DefinitionVersion != 0 || len(ag.Persona.AllowedActions) != 0 {
+		t.Errorf("expected the SKILL.md persona back, got %+v", ag.Persona)
+	}
+}
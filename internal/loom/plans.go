@@ -0,0 +1,92 @@
+package loom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// GetBeadPlan returns the execution plan proposed for a bead in plan mode
+func (a *Loom) GetBeadPlan(beadID string) (*models.ExecutionPlan, error) {
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return nil, fmt.Errorf("bead not found: %w", err)
+	}
+	plan := dispatch.BeadPlan(bead)
+	if plan == nil {
+		return nil, fmt.Errorf("bead %s has no execution plan", beadID)
+	}
+	return plan, nil
+}
+
+// ApproveBeadPlan approves a bead's proposed plan, so the bead is
+// dispatched to do the work with the plan in hand
+func (a *Loom) ApproveBeadPlan(beadID, userID string) (*models.ExecutionPlan, error) {
+	return a.reviewBeadPlan(beadID, userID, models.PlanStatusApproved, "")
+}
+
+// RejectBeadPlan rejects a bead's proposed plan, so the bead's agent
+// plans again with the reason as feedback
+func (a *Loom) RejectBeadPlan(beadID, userID, reason string) (*models.ExecutionPlan, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("a reason is required to reject a plan")
+	}
+	return a.reviewBeadPlan(beadID, userID, models.PlanStatusRejected, reason)
+}
+
+func (a *Loom) reviewBeadPlan(beadID, userID string, status models.PlanStatus, reason string) (*models.ExecutionPlan, error) {
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return nil, fmt.Errorf("bead not found: %w", err)
+	}
+	plan := dispatch.BeadPlan(bead)
+	if plan == nil {
+		return nil, fmt.Errorf("bead %s has no execution plan", beadID)
+	}
+	if plan.Status != models.PlanStatusProposed {
+		return nil, fmt.Errorf("plan for bead %s is already %s", beadID, plan.Status)
+	}
+
+	now := time.Now().UTC()
+	plan.Status = status
+	plan.ReviewedBy = userID
+	plan.ReviewReason = reason
+	plan.ReviewedAt = &now
+	if _, err := a.UpdateBead(beadID, map[string]interface{}{
+		"context": map[string]string{
+			dispatch.BeadPlanKey:   dispatch.EncodePlan(plan),
+			"redispatch_requested": "true",
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to review plan: %w", err)
+	}
+
+	eventType, action := eventbus.EventTypeBeadPlanApproved, "bead.plan_approved"
+	if status == models.PlanStatusRejected {
+		eventType, action = eventbus.EventTypeBeadPlanRejected, "bead.plan_rejected"
+	}
+	audit.Record(audit.Event{
+		Actor:     userID,
+		Action:    action,
+		Resource:  beadID,
+		ProjectID: bead.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details:   map[string]interface{}{"reason": reason, "files": len(plan.Files), "commands": len(plan.Commands)},
+	})
+	if a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventType, beadID, bead.ProjectID, map[string]interface{}{
+			"title":       bead.Title,
+			"actor_id":    userID,
+			"actor_type":  "user",
+			"reviewed_by": userID,
+			"reason":      reason,
+		})
+	}
+	return plan, nil
+}
@@ -0,0 +1,52 @@
+package loom
+
+import (
+	"context"
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/plugin"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newPluginLoader creates the dashboard panel registry and, when a plugins
+// directory is configured, the loader that fills it
+func newPluginLoader(cfg config.PluginsConfig) (*plugin.PanelRegistry, *plugin.Loader) {
+	panels := plugin.NewPanelRegistry()
+	if cfg.Dir == "" {
+		return panels, nil
+	}
+	loader := plugin.NewLoader(cfg.Dir)
+	loader.SetPanelRegistry(panels)
+	return panels, loader
+}
+
+// GetPanelRegistry returns the registry of plugin-contributed dashboard panels
+func (a *Loom) GetPanelRegistry() *plugin.PanelRegistry {
+	return a.panels
+}
+
+// loadPlugins loads the auto-start plugins of the plugins directory
+func (a *Loom) loadPlugins(ctx context.Context) {
+	if a.plugins == nil {
+		return
+	}
+	n, err := a.plugins.LoadAll(ctx)
+	if err != nil {
+		log.Printf("[Plugins] Failed to load plugins: %v", err)
+		return
+	}
+	log.Printf("[Plugins] Loaded %d plugin(s), %d dashboard panel(s)", n, len(a.panels.List()))
+}
+
+// unloadPlugins lets loaded plugins release their resources
+func (a *Loom) unloadPlugins() {
+	if a.plugins == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, p := range a.plugins.ListPlugins() {
+		if err := a.plugins.UnloadPlugin(ctx, p.Manifest.Metadata.ProviderType); err != nil {
+			log.Printf("[Plugins] Failed to unload %s: %v", p.Manifest.Metadata.ProviderType, err)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package loom
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/persona"
+	"github.com/jordanhubbard/loom/internal/policy"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Decision context keys for policy approvals
+const (
+	policyApprovalKey    = "policy_approval_fingerprint"
+	policyApprovalAction = "policy_approval_action"
+	policyApprovalRule   = "policy_approval_rule"
+)
+
+// policySpendTTL is how long the day's spend per project, bead and
+// organization is reused for budget decisions
+const policySpendTTL = time.Minute
+
+// newPolicyEngine opens the policy engine over the project policy versions
+// in db, with the global policy file of the config
+func newPolicyEngine(db *database.Database, cfg config.PolicyConfig) *policy.Engine {
+	var global *policy.Document
+	if cfg.File != "" {
+		doc, err := policy.LoadFile(cfg.File)
+		if err != nil {
+			log.Printf("[Policy] Ignoring policy file %s: %v", cfg.File, err)
+		} else {
+			global = doc
+		}
+	}
+	var store policy.Store
+	if db != nil {
+		store = db
+	}
+	return policy.NewEngine(store, global)
+}
+
+// GetPolicyEngine returns the arbiter's policy engine
+func (a *Loom) GetPolicyEngine() *policy.Engine {
+	return a.policyEngine
+}
+
+// policyGate puts dispatch and agent actions to the policy engine. It
+// gathers the attributes rules match on: the bead, the agent and its role,
+// the action, and for budget rules what the project and bead spent today.
+// It also holds each organization to its daily budget.
+type policyGate struct {
+	loom    *Loom
+	storage analytics.Storage // nil without a database; spend then reads as 0
+
+	mu       sync.Mutex
+	spentAt  time.Time
+	projects map[string]float64
+	beads    map[string]float64
+	orgs     map[string]float64
+}
+
+func newPolicyGate(a *Loom, db *database.Database) *policyGate {
+	g := &policyGate{loom: a}
+	if db != nil {
+		if storage, err := analytics.NewDatabaseStorage(db.DB()); err == nil {
+			g.storage = storage
+		}
+	}
+	return g
+}
+
+// DispatchAllowed applies the organization's daily budget and then the
+// budget policy to a bead about to be dispatched
+func (g *policyGate) DispatchAllowed(ctx context.Context, b *models.Bead) (bool, string) {
+	if ok, reason := g.orgBudgetAllows(ctx, b.ProjectID); !ok {
+		return false, reason
+	}
+	engine := g.loom.policyEngine
+	if !engine.Governs(b.ProjectID, policy.KindBudget) {
+		return true, ""
+	}
+	attrs := beadAttributes(b)
+	projectSpend, beadSpend := g.spendToday(ctx, b.ProjectID, b.ID)
+	attrs["project_cost_today_usd"] = projectSpend
+	attrs["bead_cost_today_usd"] = beadSpend
+
+	d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindBudget, ProjectID: b.ProjectID, Attributes: attrs})
+	if d.Effect == policy.EffectDeny {
+		return false, d.Reason
+	}
+	return true, ""
+}
+
+// EscalateStuckBead applies the escalation policy to a bead stuck in a loop
+func (g *policyGate) EscalateStuckBead(ctx context.Context, b *models.Bead, dispatchCount int, loopReason string) (bool, string) {
+	engine := g.loom.policyEngine
+	if !engine.Governs(b.ProjectID, policy.KindEscalation) {
+		return false, ""
+	}
+	attrs := beadAttributes(b)
+	attrs["dispatch_count"] = dispatchCount
+	attrs["loop_reason"] = loopReason
+
+	d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindEscalation, ProjectID: b.ProjectID, Attributes: attrs})
+	return d.Effect == policy.EffectEscalate, d.Reason
+}
+
+// AutoApprovePlan applies the plan approval policy to a bead's proposed
+// execution plan
+func (g *policyGate) AutoApprovePlan(ctx context.Context, b *models.Bead, plan *models.ExecutionPlan) (bool, string, string) {
+	engine := g.loom.policyEngine
+	if !engine.Governs(b.ProjectID, policy.KindPlanApproval) {
+		return false, "", ""
+	}
+	attrs := beadAttributes(b)
+	attrs["plan_files"] = len(plan.Files)
+	attrs["plan_commands"] = len(plan.Commands)
+	attrs["plan_cost_usd"] = plan.EstimatedCostUSD
+	attrs["plan_estimated_tokens"] = plan.EstimatedTokens
+	attrs["plan_paths"] = strings.Join(plan.Files, " ")
+	attrs["plan_command_text"] = strings.Join(plan.Commands, "\n")
+
+	d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindPlanApproval, ProjectID: b.ProjectID, Attributes: attrs})
+	if d.Effect != policy.EffectAllow {
+		return false, "", ""
+	}
+	audit.Record(audit.Event{
+		Actor:     "policy",
+		Action:    "policy.plan_approved",
+		Resource:  b.ID,
+		ProjectID: b.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details:   map[string]interface{}{"rule_id": d.RuleID, "source": d.Source, "files": len(plan.Files), "commands": len(plan.Commands)},
+	})
+	return true, d.RuleID, d.Reason
+}
+
+// CheckAction applies the agent's persona's allowed actions, the
+// tool-permission policy and then the approval policy to an agent action.
+// An action that needs approval opens a decision for the bead; once it is
+// approved, actions of that type on the bead run.
+func (g *policyGate) CheckAction(ctx context.Context, action actions.Action, actx actions.ActionContext) (bool, string, map[string]interface{}) {
+	if ok, reason, details := g.personaAllows(action, actx); !ok {
+		return false, reason, details
+	}
+	engine := g.loom.policyEngine
+	governsTools := engine.Governs(actx.ProjectID, policy.KindToolPermission)
+	governsApproval := engine.Governs(actx.ProjectID, policy.KindApproval)
+	if !governsTools && !governsApproval {
+		return true, "", nil
+	}
+	attrs := g.actionAttributes(action, actx)
+
+	if governsTools {
+		d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindToolPermission, ProjectID: actx.ProjectID, Attributes: attrs})
+		if d.Effect == policy.EffectDeny {
+			audit.Record(audit.Event{
+				Actor:     actx.AgentID,
+				Action:    "policy.action_denied",
+				Resource:  actx.BeadID,
+				ProjectID: actx.ProjectID,
+				Outcome:   audit.OutcomeDenied,
+				Details:   map[string]interface{}{"action": action.Type, "rule_id": d.RuleID, "source": d.Source},
+			})
+			return false, "denied by policy: " + d.Reason, map[string]interface{}{
+				"policy_rule":   d.RuleID,
+				"policy_source": d.Source,
+			}
+		}
+	}
+
+	if governsApproval {
+		d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindApproval, ProjectID: actx.ProjectID, Attributes: attrs})
+		if d.Effect == policy.EffectRequireApproval {
+			return g.requireApproval(action, actx, d)
+		}
+	}
+	return true, "", nil
+}
+
+// personaAllows checks an action against the allowed actions of the
+// agent's persona. Finishing, handing off and escalating are always allowed
+// so an agent is never left unable to hand its bead back.
+func (g *policyGate) personaAllows(action actions.Action, actx actions.ActionContext) (bool, string, map[string]interface{}) {
+	switch action.Type {
+	case actions.ActionDone, actions.ActionHandoff, actions.ActionEscalateCEO:
+		return true, "", nil
+	}
+	if actx.AgentID == "" || g.loom.agentManager == nil {
+		return true, "", nil
+	}
+	ag, err := g.loom.agentManager.GetAgent(actx.AgentID)
+	if err != nil || ag == nil || ag.PersonaName == "" {
+		return true, "", nil
+	}
+	p, err := g.loom.personaManager.LoadPersona(ag.PersonaName)
+	if err != nil || persona.AllowsAction(p, action.Type) {
+		return true, "", nil
+	}
+	audit.Record(audit.Event{
+		Actor:     actx.AgentID,
+		Action:    "persona.action_denied",
+		Resource:  actx.BeadID,
+		ProjectID: actx.ProjectID,
+		Outcome:   audit.OutcomeDenied,
+		Details:   map[string]interface{}{"action": action.Type, "persona": ag.PersonaName, "persona_version": p.DefinitionVersion},
+	})
+	return false, fmt.Sprintf("%s is not among the actions persona %s allows: %s", action.Type, ag.PersonaName, strings.Join(p.AllowedActions, ", ")),
+		map[string]interface{}{"persona": ag.PersonaName, "persona_version": p.DefinitionVersion}
+}
+
+// requireApproval lets an action through once a decision approved it, and
+// otherwise opens or points at the decision that has to
+func (g *policyGate) requireApproval(action actions.Action, actx actions.ActionContext, d policy.Decision) (bool, string, map[string]interface{}) {
+	a := g.loom
+	sum := sha256.Sum256([]byte(actx.ProjectID + "|" + actx.BeadID + "|" + action.Type))
+	fingerprint := hex.EncodeToString(sum[:8])
+	details := map[string]interface{}{"policy_rule": d.RuleID, "policy_source": d.Source}
+
+	if existing := g.findApprovalDecision(actx.ProjectID, fingerprint); existing != nil {
+		details["decision_id"] = existing.ID
+		switch strings.ToLower(strings.TrimSpace(existing.Decision)) {
+		case "approve":
+			return true, "", nil
+		case "":
+			return false, fmt.Sprintf("%s needs approval (%s); waiting on decision %s", action.Type, d.Reason, existing.ID), details
+		default:
+			return false, fmt.Sprintf("%s was denied by decision %s", action.Type, existing.ID), details
+		}
+	}
+
+	question := fmt.Sprintf("Agent %s wants to run %s on bead %s in project %s.\n\nPolicy: %s\n\nChoose: approve | deny",
+		actx.AgentID, action.Type, actx.BeadID, actx.ProjectID, d.Reason)
+	decision, err := a.CreateDecisionBead(question, actx.BeadID, "system", []string{"approve", "deny"}, "", models.BeadPriorityP1, actx.ProjectID)
+	if err != nil {
+		log.Printf("[Policy] Failed to create approval decision: %v", err)
+		return false, fmt.Sprintf("%s needs approval (%s) and the approval request failed: %v", action.Type, d.Reason, err), details
+	}
+	_ = a.decisionManager.UpdateDecisionContext(decision.ID, map[string]string{
+		policyApprovalKey:    fingerprint,
+		policyApprovalAction: action.Type,
+		policyApprovalRule:   d.RuleID,
+	})
+	audit.Record(audit.Event{
+		Actor:     actx.AgentID,
+		Action:    "policy.approval_requested",
+		Resource:  decision.ID,
+		ProjectID: actx.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details:   map[string]interface{}{"action": action.Type, "bead_id": actx.BeadID, "rule_id": d.RuleID},
+	})
+	details["decision_id"] = decision.ID
+	return false, fmt.Sprintf("%s needs approval (%s); requested in decision %s", action.Type, d.Reason, decision.ID), details
+}
+
+// findApprovalDecision returns the most recent approval decision for the
+// fingerprint, if any
+func (g *policyGate) findApprovalDecision(projectID, fingerprint string) *models.DecisionBead {
+	decisions, err := g.loom.decisionManager.GetDecisionsByProject(projectID)
+	if err != nil {
+		return nil
+	}
+	var latest *models.DecisionBead
+	for _, d := range decisions {
+		if d.Context[policyApprovalKey] != fingerprint {
+			continue
+		}
+		if latest == nil || d.CreatedAt.After(latest.CreatedAt) {
+			latest = d
+		}
+	}
+	return latest
+}
+
+// actionAttributes describes an agent action, its bead and its agent
+func (g *policyGate) actionAttributes(action actions.Action, actx actions.ActionContext) map[string]interface{} {
+	attrs := map[string]interface{}{
+		"action":     action.Type,
+		"agent_id":   actx.AgentID,
+		"bead_id":    actx.BeadID,
+		"project_id": actx.ProjectID,
+	}
+	if action.Path != "" {
+		attrs["path"] = action.Path
+	}
+	if action.Command != "" {
+		attrs["command"] = action.Command
+	}
+	if action.Branch != "" {
+		attrs["branch"] = action.Branch
+	}
+	if actx.AgentID != "" && g.loom.agentManager != nil {
+		if ag, err := g.loom.agentManager.GetAgent(actx.AgentID); err == nil && ag != nil {
+			attrs["agent_role"] = ag.Role
+			attrs["agent_persona"] = ag.PersonaName
+		}
+	}
+	if actx.BeadID != "" && g.loom.beadsManager != nil {
+		if b, err := g.loom.beadsManager.GetBead(actx.BeadID); err == nil && b != nil {
+			for k, v := range beadAttributes(b) {
+				if _, set := attrs[k]; !set {
+					attrs[k] = v
+				}
+			}
+		}
+	}
+	return attrs
+}
+
+// beadAttributes describes a bead for policy rules
+func beadAttributes(b *models.Bead) map[string]interface{} {
+	return map[string]interface{}{
+		"bead_id":       b.ID,
+		"bead_type":     b.Type,
+		"bead_priority": int(b.Priority),
+		"bead_tags":     strings.Join(b.Tags, ","),
+		"assigned_to":   b.AssignedTo,
+		"project_id":    b.ProjectID,
+	}
+}
+
+// spendToday returns what a project and a bead have spent since midnight
+// UTC, recomputed at most once per policySpendTTL
+func (g *policyGate) spendToday(ctx context.Context, projectID, beadID string) (float64, float64) {
+	if g.storage == nil {
+		return 0, 0
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.refreshSpend(ctx)
+	return g.projects[projectID], g.beads[beadID]
+}
+
+// orgBudgetAllows reports whether the organization owning a project is
+// still under its daily budget
+func (g *policyGate) orgBudgetAllows(ctx context.Context, projectID string) (bool, string) {
+	orgID := g.loom.ProjectOrg(projectID)
+	if orgID == "" || g.storage == nil {
+		return true, ""
+	}
+	o, err := g.loom.orgManager.Get(orgID)
+	if err != nil || o.DailyBudgetUSD <= 0 {
+		return true, ""
+	}
+
+	g.mu.Lock()
+	g.refreshSpend(ctx)
+	spent := g.orgs[orgID]
+	g.mu.Unlock()
+
+	if spent >= o.DailyBudgetUSD {
+		return false, fmt.Sprintf("organization %s spent $%.2f of its $%.2f daily budget", orgID, spent, o.DailyBudgetUSD)
+	}
+	return true, ""
+}
+
+// orgBudgetRemaining returns what the organization owning a project has
+// left of its daily budget, or -1 when it has none
+func (g *policyGate) orgBudgetRemaining(ctx context.Context, projectID string) float64 {
+	orgID := g.loom.ProjectOrg(projectID)
+	if orgID == "" || g.storage == nil {
+		return -1
+	}
+	o, err := g.loom.orgManager.Get(orgID)
+	if err != nil || o.DailyBudgetUSD <= 0 {
+		return -1
+	}
+
+	g.mu.Lock()
+	g.refreshSpend(ctx)
+	spent := g.orgs[orgID]
+	g.mu.Unlock()
+
+	if spent >= o.DailyBudgetUSD {
+		return 0
+	}
+	return o.DailyBudgetUSD - spent
+}
+
+// refreshSpend rereads today's spend per project, bead and organization
+// once the cached figures are stale. Callers hold g.mu.
+func (g *policyGate) refreshSpend(ctx context.Context) {
+	now := time.Now().UTC()
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if now.Sub(g.spentAt) > policySpendTTL || g.spentAt.Before(midnight) {
+		logs, err := g.storage.GetLogs(ctx, &analytics.LogFilter{StartTime: midnight, EndTime: now})
+		if err != nil {
+			log.Printf("[Policy] Failed to read today's spend: %v", err)
+		} else {
+			g.projects = make(map[string]float64)
+			g.beads = make(map[string]float64)
+			g.orgs = make(map[string]float64)
+			for _, l := range logs {
+				g.projects[l.Metadata[analytics.MetadataProjectID]] += l.CostUSD
+				g.beads[l.Metadata[analytics.MetadataBeadID]] += l.CostUSD
+			}
+			for projectID, cost := range g.projects {
+				if orgID := g.loom.ProjectOrg(projectID); orgID != "" {
+					g.orgs[orgID] += cost
+				}
+			}
+			g.spentAt = now
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/dispatch"
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/internal/prreview"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newPRReviews opens the automated review stage over db. Auto-merges wait
+// for CI, and finished reviews are published, which puts them in the
+// activity feed. Without a database there is nowhere to keep reviews.
+func newPRReviews(a *Loom, db *database.Database, cfg config.ReviewConfig) *prreview.Manager {
+	if db == nil || a.providerRegistry == nil {
+		return nil
+	}
+	host := &prReviewHost{loom: a}
+	mgr := prreview.NewManager(db, host, host, host, prreview.Config{
+		Enabled:      cfg.Enabled,
+		Persona:      cfg.Persona,
+		ProviderID:   cfg.ProviderID,
+		AutoMerge:    cfg.AutoMerge,
+		MergeMethod:  cfg.MergeMethod,
+		MaxDiffBytes: cfg.MaxDiffBytes,
+		Timeout:      cfg.Timeout,
+	})
+	mgr.SetMergeGate(func(ctx context.Context, pr prreview.PullRequest) error {
+		return a.CheckBranch(ctx, actions.ActionContext{ProjectID: pr.ProjectID, BeadID: pr.BeadID}, pr.Branch)
+	})
+	if a.eventBus == nil {
+		return mgr
+	}
+
+	eb := a.eventBus
+	mgr.OnReviewed(func(r *prreview.Review) {
+		title := fmt.Sprintf("PR #%d reviewed: %s", r.PRNumber, strings.ReplaceAll(r.Status, "_", " "))
+		if r.Merged {
+			title += ", merged"
+		}
+		_ = eb.Publish(&eventbus.Event{
+			Type:      eventbus.EventTypePRReviewed,
+			Source:    "pr-review",
+			ProjectID: r.ProjectID,
+			Data: map[string]interface{}{
+				"review_id": r.ID,
+				"title":     title,
+				"bead_id":   r.BeadID,
+				"pr_number": r.PRNumber,
+				"pr_url":    r.PRURL,
+				"status":    r.Status,
+				"blocking":  r.Blocking,
+				"findings":  len(r.Findings),
+				"merged":    r.Merged,
+				"error":     r.Error,
+			},
+		})
+	})
+	return mgr
+}
+
+// GetPRReviews returns the automated pull request review stage
+func (a *Loom) GetPRReviews() *prreview.Manager {
+	return a.prReviews
+}
+
+// PullRequestOpened satisfies actions.PullRequestReviewer: pull requests
+// agents open are reviewed when the review stage is enabled
+func (a *Loom) PullRequestOpened(ctx context.Context, actx actions.ActionContext, title string, pr map[string]interface{}) {
+	if opened, ok := openedPullRequest(actx, title, pr); ok {
+		a.prReviews.Opened(opened)
+	}
+}
+
+// openedPullRequest reads the pull request a create_pr action opened from
+// its result
+func openedPullRequest(actx actions.ActionContext, title string, pr map[string]interface{}) (prreview.PullRequest, bool) {
+	number := 0
+	switch n := pr["pr_number"].(type) {
+	case int:
+		number = n
+	case float64:
+		number = int(n)
+	}
+	branch, _ := pr["branch"].(string)
+	base, _ := pr["base"].(string)
+	url, _ := pr["pr_url"].(string)
+	return prreview.PullRequest{
+		ProjectID: actx.ProjectID,
+		BeadID:    actx.BeadID,
+		Number:    number,
+		URL:       url,
+		Branch:    branch,
+		Base:      base,
+		Title:     title,
+	}, number > 0 && branch != ""
+}
+
+// prReviewHost reads diffs from the project's git service, reviews them on
+// the registered providers and posts reviews through the gh CLI. Demo
+// projects keep their pull requests in the demo manager instead.
+type prReviewHost struct {
+	loom *Loom
+}
+
+// Diff returns the changes branch makes against base
+func (h *prReviewHost) Diff(ctx context.Context, projectID, base, branch string) (string, error) {
+	a := h.loom
+	if a.demo != nil && a.demo.HandlesProject(projectID) {
+		pulls, err := a.demo.PullRequests(projectID)
+		if err != nil {
+			return "", err
+		}
+		for i := len(pulls) - 1; i >= 0; i-- {
+			if pulls[i].Branch == branch && pulls[i].Base == base {
+				return pulls[i].Diff, nil
+			}
+		}
+		return "", fmt.Errorf("no pull request from %s into %s in demo project %s", branch, base, projectID)
+	}
+	if a.actionRouter == nil || a.actionRouter.Git == nil {
+		return "", fmt.Errorf("git operator not configured")
+	}
+	result, err := a.actionRouter.Git.DiffBranches(actions.WithProjectID(ctx, projectID), base, branch)
+	if err != nil {
+		return "", err
+	}
+	diff, _ := result["diff"].(string)
+	return diff, nil
+}
+
+// Complete sends the review prompt with the persona's instructions as the
+// system prompt, to providerID or to the best active provider for the
+// persona's model tier. The demo's scripted provider cannot review, so it
+// is only used when configured.
+func (h *prReviewHost) Complete(ctx context.Context, personaName, providerID, prompt string) (string, string, string, error) {
+	a := h.loom
+	p, err := a.personaManager.LoadPersona(dispatch.QualifyPersonaName(personaName))
+	if err != nil {
+		return "", "", "", fmt.Errorf("unknown reviewer persona %s: %w", personaName, err)
+	}
+	if providerID == "" {
+		candidates := a.providerRegistry.ListActiveForComplexity(dispatch.TierComplexity(p.ModelTier))
+		for _, c := range candidates {
+			if c != nil && c.Config != nil && !slices.Contains(c.Config.Tags, provider.DemoTag) {
+				providerID = c.Config.ID
+				break
+			}
+		}
+		if providerID == "" {
+			return "", "", "", fmt.Errorf("no active provider to review with")
+		}
+	}
+	systemPrompt := p.Instructions
+	if systemPrompt == "" {
+		systemPrompt = p.Character
+	}
+	response, model, err := (&goldenCompleter{registry: a.providerRegistry}).Complete(ctx, providerID, systemPrompt, prompt)
+	return response, providerID, model, err
+}
+
+// Comment posts a finding on the pull request. gh comments are not
+// anchored to lines, so the finding names its file and line in the body.
+func (h *prReviewHost) Comment(ctx context.Context, projectID string, number int, path string, line int, body string) error {
+	a := h.loom
+	if a.demo != nil && a.demo.HandlesProject(projectID) {
+		return a.demo.AddComment(ctx, projectID, number, path, line, body, "pr-review")
+	}
+	return h.gh(ctx, projectID, fmt.Sprintf("gh pr comment %d --body %q", number, body))
+}
+
+// Submit approves the pull request or requests changes
+func (h *prReviewHost) Submit(ctx context.Context, projectID string, number int, event, body string) error {
+	a := h.loom
+	if a.demo != nil && a.demo.HandlesProject(projectID) {
+		_, err := a.demo.SubmitReview(ctx, projectID, number, event, body, "pr-review")
+		return err
+	}
+	flag := "--" + strings.ToLower(strings.ReplaceAll(event, "_", "-"))
+	return h.gh(ctx, projectID, fmt.Sprintf("gh pr review %d %s --body %q", number, flag, body))
+}
+
+// Merge merges the pull request
+func (h *prReviewHost) Merge(ctx context.Context, projectID string, number int, method string) error {
+	switch method {
+	case "merge", "squash", "rebase":
+	default:
+		return fmt.Errorf("invalid merge method %q", method)
+	}
+	a := h.loom
+	if a.demo != nil && a.demo.HandlesProject(projectID) {
+		return a.demo.MergePR(ctx, projectID, number)
+	}
+	return h.gh(ctx, projectID, fmt.Sprintf("gh pr merge %d --%s", number, method))
+}
+
+// gh runs a gh command in the project's work directory
+func (h *prReviewHost) gh(ctx context.Context, projectID, command string) error {
+	res, err := h.loom.ExecuteCommand(ctx, executor.ExecuteCommandRequest{
+		AgentID:   "pr-review",
+		ProjectID: projectID,
+		Command:   command,
+	})
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		msg := strings.TrimSpace(res.Stderr)
+		if msg == "" {
+			msg = res.Error
+		}
+		return fmt.Errorf("gh failed: %s", msg)
+	}
+	return nil
+}
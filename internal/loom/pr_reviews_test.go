@@ -0,0 +1,94 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// reviewProtocol answers every chat with a fixed review and keeps the
+// system prompt it was sent
+type reviewProtocol struct {
+	answer       string
+	systemPrompt string
+}
+
+func (p *reviewProtocol) CreateChatCompletion(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	if len(req.Messages) > 0 && req.Messages[0].Role == "system" {
+		p.systemPrompt = req.Messages[0].Content
+	}
+	resp := &provider.ChatCompletionResponse{Model: "review-model"}
+	resp.Choices = append(resp.Choices, struct {
+		Index   int                  `json:"index"`
+		Message provider.ChatMessage `json:"message"`
+		Finish  string               `json:"finish_reason"`
+	}{Message: provider.ChatMessage{Role: "assistant", Content: p.answer}})
+	return resp, nil
+}
+
+func (p *reviewProtocol) GetModels(ctx context.Context) ([]provider.Model, error) {
+	return nil, nil
+}
+
+func TestPRReviewHost_ReviewsAsPersonaOnTierProvider(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	if l.GetPRReviews() == nil {
+		t.Fatal("expected the review stage to be configured")
+	}
+
+	proto := &reviewProtocol{answer: `{"summary": "ok", "findings": []}`}
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "demo-mock", Type: "mock", Status: "active", Tags: []string{provider.DemoTag}}, &reviewProtocol{})
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "reviewer", Type: "mock", Status: "active", Model: "review-model"}, proto)
+
+	host := &prReviewHost{loom: l}
+	response, providerID, model, err := host.Complete(context.Background(), "code-reviewer", "", "review this")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	if providerID != "reviewer" || model != "review-model" || response != proto.answer {
+		t.Errorf("Complete = %q on %s/%s", response, providerID, model)
+	}
+	if proto.systemPrompt == "" {
+		t.Error("expected the reviewer persona's instructions as the system prompt")
+	}
+	if _, _, _, err := host.Complete(context.Background(), "no-such-persona", "", "review this"); err == nil {
+		t.Error("expected an unknown persona to be refused")
+	}
+	if err := host.Merge(context.Background(), "proj-1", 1, "octopus"); err == nil || !strings.Contains(err.Error(), "merge method") {
+		t.Errorf("expected an invalid merge method to be refused, got %v", err)
+	}
+}
+
+func TestPullRequestOpened_OnlyReviewsWhenEnabled(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	pr := map[string]interface{}{"pr_number": 3, "pr_url": "https://example.com/pull/3", "branch": "agent/bd-1", "base": "main"}
+	l.PullRequestOpened(context.Background(), actions.ActionContext{ProjectID: "proj-1", BeadID: "bd-1"}, "Fix", pr)
+	if list, _ := l.GetPRReviews().List("proj-1", 0); len(list) != 0 {
+		t.Fatalf("the review stage is off by default, got %d reviews", len(list))
+	}
+
+	l, tmpDir = testLoom(t, func(c *config.Config) { c.Review = config.ReviewConfig{Enabled: true, ProviderID: "missing"} })
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	opened, ok := openedPullRequest(actions.ActionContext{ProjectID: "proj-1", BeadID: "bd-1"}, "Fix", pr)
+	if !ok || opened.Number != 3 || opened.Branch != "agent/bd-1" {
+		t.Fatalf("openedPullRequest = %+v, %v", opened, ok)
+	}
+	if _, ok := openedPullRequest(actions.ActionContext{}, "", map[string]interface{}{"pr_url": "x"}); ok {
+		t.Error("a result without a number and branch is not a pull request")
+	}
+	review, err := l.GetPRReviews().Review(context.Background(), opened)
+	if err == nil || review == nil || review.Status != "error" {
+		t.Fatalf("expected a recorded error without a diff, got %+v, %v", review, err)
+	}
+	if review.BeadID != "bd-1" || review.PRURL != "https://example.com/pull/3" {
+		t.Errorf("pull request not recorded: %+v", review)
+	}
+}
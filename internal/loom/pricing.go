@@ -0,0 +1,36 @@
+package loom
+
+import (
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/pricing"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newPricing opens the pricing catalog over db, with the prices set in the
+// models' metadata over the dataset's. Without a database prices are kept
+// in memory and reloaded from the dataset at startup.
+func newPricing(db *database.Database, cfg config.ModelsConfig) *pricing.Catalog {
+	pc := pricing.DefaultConfig()
+	if cfg.Pricing.RefreshInterval > 0 {
+		pc.Interval = cfg.Pricing.RefreshInterval
+	}
+	pc.DatasetPath = cfg.Pricing.DatasetPath
+	for _, m := range cfg.Metadata {
+		if m.InputCostPerMToken > 0 || m.OutputCostPerMToken > 0 {
+			pc.Overrides = append(pc.Overrides, pricing.Price{
+				Model:           m.Model,
+				InputPerMToken:  m.InputCostPerMToken,
+				OutputPerMToken: m.OutputCostPerMToken,
+			})
+		}
+	}
+	if db == nil {
+		return pricing.NewCatalog(nil, pc)
+	}
+	return pricing.NewCatalog(db, pc)
+}
+
+// GetPricing returns the pricing catalog
+func (a *Loom) GetPricing() *pricing.Catalog {
+	return a.pricing
+}
@@ -0,0 +1,231 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/eventhooks"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// newProjectTemplates keeps project templates in db. Without a database
+// there is nowhere to keep them.
+func newProjectTemplates(db *database.Database) *projecttemplates.Manager {
+	if db == nil {
+		return nil
+	}
+	return projecttemplates.NewManager(db)
+}
+
+// GetProjectTemplates returns the project template manager
+func (a *Loom) GetProjectTemplates() *projecttemplates.Manager {
+	return a.projectTemplates
+}
+
+// PlanProjectTemplate resolves what instantiating t with req would create
+// and lists the issues that would stop it: personas that do not exist and
+// providers that are unknown or reserved for another organization.
+func (a *Loom) PlanProjectTemplate(t *projecttemplates.Template, req projecttemplates.InstantiateRequest) *projecttemplates.Result {
+	res := &projecttemplates.Result{
+		TemplateID: t.ID,
+		DryRun:     req.DryRun,
+		Name:       firstNonEmpty(req.Name, t.Name),
+		GitRepo:    firstNonEmpty(req.GitRepo, t.GitRepo),
+		Branch:     firstNonEmpty(req.Branch, t.Branch),
+		OrgID:      org.Normalize(req.OrgID),
+	}
+	if res.GitRepo == "" {
+		res.Issues = append(res.Issues, "git_repo is required: the template has none")
+	}
+	if t.OrgID != "" && t.OrgID != res.OrgID {
+		res.Issues = append(res.Issues, fmt.Sprintf("template belongs to organization %s", t.OrgID))
+	}
+	for _, name := range t.Personas {
+		if _, err := a.personaManager.LoadPersona(name); err != nil {
+			res.Issues = append(res.Issues, fmt.Sprintf("persona %s not found", name))
+		}
+	}
+	for role, providerID := range t.Providers {
+		if _, err := a.providerRegistry.Get(providerID); err != nil {
+			res.Issues = append(res.Issues, fmt.Sprintf("provider %s for %s not found", providerID, role))
+		} else if reserved := a.providerOrg(providerID); reserved != "" && reserved != res.OrgID {
+			res.Issues = append(res.Issues, fmt.Sprintf("provider %s for %s is reserved for organization %s", providerID, role, reserved))
+		}
+	}
+	return res
+}
+
+// InstantiateProjectTemplate makes a project from a template: it registers
+// the project, generates its SSH key, clones the repository and loads the
+// beads it already has, staffs the template's personas, assigns providers,
+// files the seed beads and subscribes the notification endpoints. Once the
+// project exists, later steps that fail are reported as warnings so the
+// caller can finish them by hand.
+func (a *Loom) InstantiateProjectTemplate(ctx context.Context, t *projecttemplates.Template, req projecttemplates.InstantiateRequest) (*projecttemplates.Result, error) {
+	res := a.PlanProjectTemplate(t, req)
+	if len(res.Issues) > 0 {
+		return nil, fmt.Errorf("template %s cannot be instantiated: %s", t.ID, strings.Join(res.Issues, "; "))
+	}
+	if req.DryRun {
+		return res, nil
+	}
+
+	projectContext := map[string]string{}
+	for k, v := range t.Context {
+		projectContext[k] = v
+	}
+	for k, v := range req.Context {
+		projectContext[k] = v
+	}
+	projectContext["template_id"] = t.ID
+
+	p, err := a.CreateProjectInOrg(res.OrgID, res.Name, res.GitRepo, res.Branch, t.BeadsPath, projectContext)
+	if err != nil {
+		return nil, err
+	}
+	warn := func(format string, args ...interface{}) {
+		res.Warnings = append(res.Warnings, fmt.Sprintf(format, args...))
+	}
+
+	if p.GitAuthMethod == models.GitAuthSSH {
+		if pubKey, err := a.gitopsManager.EnsureProjectSSHKey(p.ID); err != nil {
+			warn("failed to generate SSH key: %v", err)
+		} else {
+			res.PublicKey = pubKey
+			res.GitSetupInstructions = fmt.Sprintf(
+				"Add this public key as a deploy key (with write access) to your repository:\n\n%s\n\n"+
+					"GitHub: Repository Settings > Deploy keys > Add deploy key\n"+
+					"GitLab: Repository Settings > Repository > Deploy Keys > Add key",
+				pubKey,
+			)
+		}
+	}
+	if err := a.cloneNewProject(ctx, p); err != nil {
+		warn("failed to clone %s: %v; register the deploy key and restart loom to clone it", p.GitRepo, err)
+	} else {
+		res.Cloned = true
+	}
+
+	a.staffTemplatePersonas(ctx, t, p.ID, warn)
+	for _, ag := range a.agentManager.ListAgentsByProject(p.ID) {
+		res.AgentIDs = append(res.AgentIDs, ag.ID)
+		if providerID := t.ProviderFor(ag.Role); providerID != "" && providerID != ag.ProviderID {
+			if err := a.assignAgentProvider(ctx, ag, providerID); err != nil {
+				warn("failed to assign provider %s to %s: %v", providerID, ag.Name, err)
+			}
+		}
+	}
+
+	for _, seed := range t.Beads {
+		bead, err := a.CreateBead(seed.Title, seed.Description, models.BeadPriority(seed.Priority), seed.Type, p.ID)
+		if err != nil {
+			warn("failed to file bead %q: %v", seed.Title, err)
+			continue
+		}
+		res.BeadIDs = append(res.BeadIDs, bead.ID)
+	}
+
+	if len(t.Notifications) > 0 && a.eventWebhooks == nil {
+		warn("notification rules skipped: event webhooks need a database")
+	} else {
+		for _, rule := range t.Notifications {
+			name, url := rule.Name, rule.URL
+			if name == "" {
+				name = fmt.Sprintf("%s (%s)", p.Name, t.Name)
+			}
+			sub, err := a.eventWebhooks.Create(eventhooks.SubscriptionRequest{
+				Name:       &name,
+				URL:        &url,
+				EventTypes: rule.EventTypes,
+				ProjectIDs: &[]string{p.ID},
+			}, t.CreatedBy)
+			if err != nil {
+				warn("failed to subscribe %s: %v", rule.URL, err)
+				continue
+			}
+			res.Webhooks = append(res.Webhooks, projecttemplates.WebhookResult{ID: sub.ID, URL: sub.URL, Secret: sub.Secret})
+		}
+	}
+
+	a.PersistProject(p.ID)
+	res.Project, _ = a.projectManager.GetProject(p.ID)
+	return res, nil
+}
+
+// cloneNewProject clones a newly registered project and loads the beads
+// already in its repository, as startup does for every registered project
+func (a *Loom) cloneNewProject(ctx context.Context, p *models.Project) error {
+	if p.GitRepo == "" || p.GitRepo == "." {
+		return nil
+	}
+	if err := a.gitopsManager.CloneProject(ctx, p); err != nil {
+		return err
+	}
+	beadsPath := filepath.Join(p.WorkDir, p.BeadsPath)
+	_ = a.beadsManager.LoadProjectPrefixFromConfig(p.ID, beadsPath)
+	if p.BeadPrefix != "" {
+		a.beadsManager.SetProjectPrefix(p.ID, p.BeadPrefix)
+	}
+	if err := a.beadsManager.LoadBeadsFromFilesystem(p.ID, beadsPath); err != nil {
+		return err
+	}
+	a.startOnboardingScan(p.ID)
+	return nil
+}
+
+// staffTemplatePersonas adds an agent for each of the template's personas
+// the default org chart did not already staff
+func (a *Loom) staffTemplatePersonas(ctx context.Context, t *projecttemplates.Template, projectID string, warn func(string, ...interface{})) {
+	staffed := map[string]bool{}
+	for _, ag := range a.agentManager.ListAgentsByProject(projectID) {
+		staffed[ag.PersonaName] = true
+	}
+	for _, name := range t.Personas {
+		if staffed[name] {
+			continue
+		}
+		role := roleFromPersonaName(name)
+		ag, err := a.CreateAgent(ctx, formatAgentName(role, "Default"), name, projectID, role)
+		if err != nil {
+			warn("failed to staff %s: %v", name, err)
+			continue
+		}
+		_ = a.orgChartManager.AssignAgentToRole(projectID, role, ag.ID)
+		staffed[name] = true
+	}
+}
+
+// assignAgentProvider points an agent at a provider. The agent goes to
+// work when the provider is active; otherwise it waits paused until the
+// provider comes back.
+func (a *Loom) assignAgentProvider(ctx context.Context, ag *models.Agent, providerID string) error {
+	updated := *ag
+	updated.ProviderID = providerID
+	if a.providerRegistry.IsActive(providerID) {
+		updated.Status = "idle"
+	}
+	updated.LastActive = time.Now()
+	if a.database != nil {
+		if err := a.database.UpsertAgent(&updated); err != nil {
+			return err
+		}
+	}
+	_, err := a.agentManager.RestoreAgentWorker(ctx, &updated)
+	return err
+}
+
+// firstNonEmpty returns the first of values that is not empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestInstantiateProjectTemplate(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+	for _, cfg := range []*provider.ProviderConfig{
+		{ID: "shared", Type: "openai", Endpoint: "http://localhost:8000/v1", Model: "m", Status: "active"},
+		{ID: "acme-only", Type: "openai", Endpoint: "http://localhost:8001/v1", Model: "m", Status: "active", OrgID: "acme"},
+	} {
+		if err := l.providerRegistry.Register(cfg); err != nil {
+			t.Fatalf("Register %s: %v", cfg.ID, err)
+		}
+		if err := l.GetDatabase().UpsertProvider(&internalmodels.Provider{ID: cfg.ID, Name: cfg.ID, Type: cfg.Type, Endpoint: cfg.Endpoint, Status: cfg.Status, OrgID: cfg.OrgID}); err != nil {
+			t.Fatalf("UpsertProvider %s: %v", cfg.ID, err)
+		}
+	}
+
+	name := "Go service"
+	tmpl, err := l.GetProjectTemplates().Create("", projecttemplates.Request{
+		Name:      &name,
+		Personas:  &[]string{"default/web-designer"},
+		Providers: &map[string]string{projecttemplates.AnyRole: "shared"},
+		Beads:     &[]projecttemplates.SeedBead{{Title: "Set up CI", Priority: 1}},
+	}, "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	// Without a repository, or with a provider reserved for another
+	// organization, the template cannot be instantiated
+	plan := l.PlanProjectTemplate(tmpl, projecttemplates.InstantiateRequest{DryRun: true})
+	if len(plan.Issues) != 1 || !strings.Contains(plan.Issues[0], "git_repo") {
+		t.Errorf("expected a missing git_repo issue, got %v", plan.Issues)
+	}
+	tmpl.Providers["web-designer"] = "acme-only"
+	if _, err := l.InstantiateProjectTemplate(context.Background(), tmpl, projecttemplates.InstantiateRequest{GitRepo: "x"}); err == nil || !strings.Contains(err.Error(), "reserved") {
+		t.Errorf("expected the reserved provider to stop instantiation, got %v", err)
+	}
+	delete(tmpl.Providers, "web-designer")
+
+	repo := filepath.Join(t.TempDir(), "missing.git")
+	res, err := l.InstantiateProjectTemplate(context.Background(), tmpl, projecttemplates.InstantiateRequest{GitRepo: repo, Name: "svc"})
+	if err != nil {
+		t.Fatalf("InstantiateProjectTemplate failed: %v", err)
+	}
+	if res.Project == nil || res.Project.Name != "svc" || res.Project.Context["template_id"] != tmpl.ID || res.Project.BeadsPath != ".beads" {
+		t.Fatalf("unexpected project %+v", res.Project)
+	}
+	if res.Cloned || res.PublicKey == "" {
+		t.Errorf("expected a generated key and a failed clone, got cloned=%v key=%q", res.Cloned, res.PublicKey)
+	}
+	if len(res.BeadIDs) != 1 {
+		t.Errorf("expected one seed bead, got %v (warnings %v)", res.BeadIDs, res.Warnings)
+	}
+
+	designer := false
+	for _, ag := range l.agentManager.ListAgentsByProject(res.Project.ID) {
+		if ag.PersonaName == "default/web-designer" {
+			designer = true
+		}
+		if ag.ProviderID != "shared" {
+			t.Errorf("agent %s has provider %q, want shared", ag.Name, ag.ProviderID)
+		}
+	}
+	if !designer {
+		t.Error("expected the template's web designer to be staffed")
+	}
+}
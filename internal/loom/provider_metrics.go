@@ -0,0 +1,81 @@
+package loom
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+const (
+	defaultProviderMetricsInterval  = 5 * time.Minute
+	defaultProviderMetricsRetention = 30 * 24 * time.Hour
+)
+
+// restoreProviderMetrics loads the scorer metrics saved before the last
+// shutdown into the registered providers
+func (a *Loom) restoreProviderMetrics() {
+	if a.database == nil || a.providerRegistry == nil {
+		return
+	}
+	metrics, err := a.database.ListProviderMetrics()
+	if err != nil {
+		log.Printf("[Scoring] Failed to load provider metrics: %v", err)
+		return
+	}
+	if n := a.providerRegistry.RestoreScoreMetrics(metrics); n > 0 {
+		log.Printf("[Scoring] Restored metrics of %d provider(s)", n)
+	}
+}
+
+// saveProviderMetrics saves the scorer's current metrics and records them
+// in the metrics history
+func (a *Loom) saveProviderMetrics(now time.Time) {
+	if a.database == nil || a.providerRegistry == nil {
+		return
+	}
+	if err := a.database.SaveProviderMetrics(a.providerRegistry.ScoreMetrics(now)); err != nil {
+		log.Printf("[Scoring] Failed to save provider metrics: %v", err)
+	}
+}
+
+// runProviderMetricsPersistence saves the scorer's metrics every persist
+// interval and deletes history past its retention, until ctx is done
+func (a *Loom) runProviderMetricsPersistence(ctx context.Context) {
+	if a.database == nil || a.providerRegistry == nil {
+		return
+	}
+	cfg := a.config.Models.Scoring
+	interval := cfg.PersistInterval
+	if interval <= 0 {
+		interval = defaultProviderMetricsInterval
+	}
+	retention := cfg.HistoryRetention
+	if retention <= 0 {
+		retention = defaultProviderMetricsRetention
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			a.saveProviderMetrics(now)
+			if _, err := a.database.DeleteProviderMetricsHistory(now.Add(-retention)); err != nil {
+				log.Printf("[Scoring] Failed to prune provider metrics history: %v", err)
+			}
+		}
+	}
+}
+
+// GetProviderMetricsHistory returns the scorer metrics recorded for a
+// provider since since, oldest first; an empty providerID covers every
+// provider
+func (a *Loom) GetProviderMetricsHistory(providerID string, since time.Time, limit int) ([]provider.ScoreMetrics, error) {
+	if a.database == nil {
+		return nil, nil
+	}
+	return a.database.ListProviderMetricsHistory(providerID, since, limit)
+}
@@ -0,0 +1,40 @@
+package loom
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestProviderMetrics_SurviveRestart(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	cfg := &provider.ProviderConfig{ID: "scored", Type: "mock", Status: "active", Model: "m", ModelParamsB: 70}
+	l.providerRegistry.UpsertProtocol(cfg, &reviewProtocol{})
+	for i := 0; i < 4; i++ {
+		l.providerRegistry.RecordRequestMetrics("scored", 800, i%2 == 0)
+	}
+	before, _ := l.providerRegistry.GetScorer().GetScore("scored")
+	l.saveProviderMetrics(time.Now())
+
+	// A restart registers the provider again with no metrics
+	l.providerRegistry = provider.NewRegistry()
+	l.providerRegistry.UpsertProtocol(&provider.ProviderConfig{ID: "scored", Type: "mock", Status: "active", Model: "m"}, &reviewProtocol{})
+	l.restoreProviderMetrics()
+
+	after, ok := l.providerRegistry.GetScorer().GetScore("scored")
+	if !ok || after.AvgRequestLatencyMs != 800 || after.ModelParamsB != 70 || after.ErrorRate < 0.49 || after.ErrorRate > 0.51 {
+		t.Fatalf("expected the saved metrics back, got %+v", after)
+	}
+	if diff := after.CompositeScore - before.CompositeScore; diff > 0.01 || diff < -0.01 {
+		t.Errorf("composite %f after restart, %f before", after.CompositeScore, before.CompositeScore)
+	}
+
+	history, err := l.GetProviderMetricsHistory("scored", time.Now().Add(-time.Hour), 0)
+	if err != nil || len(history) != 1 {
+		t.Errorf("GetProviderMetricsHistory = %+v, %v", history, err)
+	}
+}
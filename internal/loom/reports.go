@@ -0,0 +1,294 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/beads"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/goldenprompts"
+	"github.com/jordanhubbard/loom/internal/notifications"
+	"github.com/jordanhubbard/loom/internal/patterns"
+	"github.com/jordanhubbard/loom/internal/pricing"
+	"github.com/jordanhubbard/loom/internal/project"
+	"github.com/jordanhubbard/loom/internal/reports"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// reportTopN bounds the rows of each ranked report table
+const reportTopN = 10
+
+// reportNotificationEvent is the event type of notifications carrying a
+// scheduled report
+const reportNotificationEvent = "report.delivered"
+
+// newReportScheduler registers Loom's reports and the destinations that are
+// configured. Email needs SMTP_HOST and S3 needs AWS credentials; Slack
+// needs nothing beyond each destination's webhook URL, and notification
+// destinations go through the users' own notification channels. Without a
+// database there is nowhere to keep schedules.
+func newReportScheduler(db *database.Database, projectMgr *project.Manager, beadsMgr *beads.Manager, golden *goldenprompts.Manager, notifier *notifications.Manager, prices *pricing.Catalog) *reports.Manager {
+	if db == nil {
+		return nil
+	}
+	mgr := reports.NewManager(db, reports.DefaultConfig())
+
+	if storage, err := analytics.NewDatabaseStorage(db.DB()); err == nil {
+		mgr.RegisterGenerator(reports.ReportCost, costReport(storage))
+		mgr.RegisterGenerator(reports.ReportPromptAnalysis, promptAnalysisReport(storage, prices))
+	} else {
+		log.Printf("Warning: cost and prompt analysis reports unavailable: %v", err)
+	}
+	mgr.RegisterGenerator(reports.ReportProjectHealth, projectHealthReport(projectMgr, beadsMgr))
+	if golden != nil {
+		mgr.RegisterGenerator(reports.ReportGoldenPrompts, goldenPromptsReport(projectMgr, golden))
+	}
+
+	if smtp := analytics.SMTPConfigFromEnv(); smtp != nil {
+		mgr.RegisterDeliverer(reports.DestinationEmail, reports.NewEmailDeliverer(smtp))
+	}
+	mgr.RegisterDeliverer(reports.DestinationSlack, reports.NewSlackDeliverer())
+	if s3 := reports.NewS3DelivererFromEnv(); s3 != nil {
+		mgr.RegisterDeliverer(reports.DestinationS3, s3)
+	}
+	if notifier != nil {
+		mgr.RegisterDeliverer(reports.DestinationNotification, reports.NewNotificationDeliverer(
+			func(ctx context.Context, userID, title, message string) error {
+				return notifier.Notify(&notifications.Notification{
+					UserID:    userID,
+					EventType: reportNotificationEvent,
+					Title:     title,
+					Message:   message,
+				})
+			}))
+	}
+	return mgr
+}
+
+// GetReportScheduler returns the scheduled report manager, or nil without a
+// database
+func (a *Loom) GetReportScheduler() *reports.Manager {
+	return a.reportScheduler
+}
+
+// costReport summarizes spend over the period and where it went, compared
+// with the period before it
+func costReport(storage analytics.Storage) reports.Generator {
+	return func(ctx context.Context, req reports.Request) (*reports.Report, error) {
+		stats, err := storage.GetLogStats(ctx, &analytics.LogFilter{StartTime: req.Start, EndTime: req.End})
+		if err != nil {
+			return nil, err
+		}
+		window := analytics.TimeRange{Start: req.Start, End: req.End}
+		baseline := analytics.TimeRange{Start: req.Start.Add(-req.End.Sub(req.Start)), End: req.Start}
+		drill, err := analytics.DrilldownCost(ctx, storage, "", window, baseline, reportTopN)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &reports.Report{
+			Title: "Cost Report",
+			Summary: []reports.Metric{
+				{Label: "Total cost", Value: fmt.Sprintf("$%.2f", stats.TotalCostUSD)},
+				{Label: "Previous period", Value: fmt.Sprintf("$%.2f", drill.BaselineCostUSD)},
+				{Label: "Change", Value: fmt.Sprintf("%+.2f USD", drill.IncreaseUSD)},
+				{Label: "Requests", Value: fmt.Sprintf("%d", stats.TotalRequests)},
+				{Label: "Tokens", Value: fmt.Sprintf("%d", stats.TotalTokens)},
+				{Label: "Error rate", Value: fmt.Sprintf("%.1f%%", stats.ErrorRate*100)},
+			},
+		}
+
+		providers := reports.Table{Title: "Cost by Provider", Columns: []string{"Provider", "Requests", "Tokens", "Cost (USD)"}}
+		for _, id := range sortedByCost(stats.CostByProvider) {
+			providers.Rows = append(providers.Rows, []string{
+				id,
+				fmt.Sprintf("%d", stats.RequestsByProvider[id]),
+				fmt.Sprintf("%d", stats.TokensByProvider[id]),
+				fmt.Sprintf("%.2f", stats.CostByProvider[id]),
+			})
+		}
+
+		projects := reports.Table{Title: "Cost by Project", Columns: []string{"Project", "Requests", "Cost (USD)", "Previous (USD)", "Change (USD)"}}
+		for _, c := range drill.ByProject {
+			projects.Rows = append(projects.Rows, []string{
+				c.Key,
+				fmt.Sprintf("%d", c.Requests),
+				fmt.Sprintf("%.2f", c.CostUSD),
+				fmt.Sprintf("%.2f", c.BaselineCostUSD),
+				fmt.Sprintf("%+.2f", c.IncreaseUSD),
+			})
+		}
+
+		dispatches := reports.Table{Title: "Costliest Dispatches", Columns: []string{"Dispatch", "Bead", "Project", "Agent", "Cost (USD)"}}
+		for _, d := range drill.TopDispatches {
+			dispatches.Rows = append(dispatches.Rows, []string{
+				d.DispatchID, d.BeadID, d.ProjectID, d.AgentID, fmt.Sprintf("%.2f", d.CostUSD),
+			})
+		}
+
+		r.Tables = []reports.Table{providers, projects, dispatches}
+		return r, nil
+	}
+}
+
+// sortedByCost returns the keys of a cost map, most expensive first
+func sortedByCost(costs map[string]float64) []string {
+	keys := make([]string, 0, len(costs))
+	for k := range costs {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if costs[keys[i]] != costs[keys[j]] {
+			return costs[keys[i]] > costs[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// promptAnalysisReport lists the prompts that could be made cheaper, with
+// savings at the catalog's prices. The optimizer looks back from now, so
+// the period's length sets its window.
+func promptAnalysisReport(storage analytics.Storage, prices *pricing.Catalog) reports.Generator {
+	return func(ctx context.Context, req reports.Request) (*reports.Report, error) {
+		cfg := patterns.DefaultPromptAnalysisConfig()
+		cfg.TimeWindow = req.End.Sub(req.Start)
+		optimizer := patterns.NewPromptOptimizer(storage, cfg)
+		optimizer.SetPricer(prices)
+		analysis, err := optimizer.AnalyzePrompts(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &reports.Report{
+			Title: "Prompt Analysis Report",
+			Summary: []reports.Metric{
+				{Label: "Prompts analyzed", Value: fmt.Sprintf("%d", analysis.TotalPrompts)},
+				{Label: "Optimizable prompts", Value: fmt.Sprintf("%d", analysis.OptimizablePrompts)},
+				{Label: "Token savings", Value: fmt.Sprintf("%d", analysis.TotalTokenSavings)},
+				{Label: "Cost savings", Value: fmt.Sprintf("$%.2f", analysis.TotalCostSavingsUSD)},
+				{Label: "Monthly projection", Value: fmt.Sprintf("$%.2f", analysis.MonthlyProjection)},
+			},
+		}
+		opts := reports.Table{Title: "Top Optimizations", Columns: []string{"Type", "Tokens saved", "Saving", "Monthly (USD)", "Recommendation"}}
+		for i, o := range analysis.Optimizations {
+			if i == reportTopN {
+				break
+			}
+			opts.Rows = append(opts.Rows, []string{
+				o.Type,
+				fmt.Sprintf("%d", o.TokenSavings),
+				fmt.Sprintf("%.0f%%", o.TokenSavingsPercent),
+				fmt.Sprintf("%.2f", o.MonthlyCostSavingsUSD),
+				o.Recommendation,
+			})
+		}
+		r.Tables = []reports.Table{opts}
+		return r, nil
+	}
+}
+
+// projectHealthReport counts each project's beads by status, how many are
+// ready to dispatch, how many a loop detector has flagged and how many were
+// closed in the period. The project_id param limits it to one project.
+func projectHealthReport(projectMgr *project.Manager, beadsMgr *beads.Manager) reports.Generator {
+	return func(ctx context.Context, req reports.Request) (*reports.Report, error) {
+		if projectMgr == nil || beadsMgr == nil {
+			return nil, fmt.Errorf("projects are not available")
+		}
+		only := req.Params["project_id"]
+
+		table := reports.Table{
+			Title:   "Projects",
+			Columns: []string{"Project", "Open", "In progress", "Blocked", "Ready", "Stuck", "Closed in period"},
+		}
+		var open, blocked, stuck, closed, count int
+		for _, p := range projectMgr.ListProjects() {
+			if p == nil || (only != "" && p.ID != only) {
+				continue
+			}
+			list, err := beadsMgr.ListBeads(map[string]interface{}{"project_id": p.ID})
+			if err != nil {
+				return nil, err
+			}
+			byStatus := make(map[models.BeadStatus]int)
+			var pStuck, pClosed int
+			for _, b := range list {
+				byStatus[b.Status]++
+				if b.Status != models.BeadStatusClosed && b.Context["loop_detected"] == "true" {
+					pStuck++
+				}
+				if b.ClosedAt != nil && !b.ClosedAt.Before(req.Start) && b.ClosedAt.Before(req.End) {
+					pClosed++
+				}
+			}
+			ready, _ := beadsMgr.GetReadyBeads(p.ID)
+
+			name := p.ID
+			if p.Name != "" && p.Name != p.ID {
+				name = fmt.Sprintf("%s (%s)", p.Name, p.ID)
+			}
+			table.Rows = append(table.Rows, []string{
+				name,
+				fmt.Sprintf("%d", byStatus[models.BeadStatusOpen]),
+				fmt.Sprintf("%d", byStatus[models.BeadStatusInProgress]),
+				fmt.Sprintf("%d", byStatus[models.BeadStatusBlocked]),
+				fmt.Sprintf("%d", len(ready)),
+				fmt.Sprintf("%d", pStuck),
+				fmt.Sprintf("%d", pClosed),
+			})
+			count++
+			open += byStatus[models.BeadStatusOpen] + byStatus[models.BeadStatusInProgress]
+			blocked += byStatus[models.BeadStatusBlocked]
+			stuck += pStuck
+			closed += pClosed
+		}
+		if only != "" && count == 0 {
+			return nil, fmt.Errorf("project %s not found", only)
+		}
+
+		return &reports.Report{
+			Title: "Project Health Report",
+			Summary: []reports.Metric{
+				{Label: "Projects", Value: fmt.Sprintf("%d", count)},
+				{Label: "Active beads", Value: fmt.Sprintf("%d", open)},
+				{Label: "Blocked beads", Value: fmt.Sprintf("%d", blocked)},
+				{Label: "Stuck beads", Value: fmt.Sprintf("%d", stuck)},
+				{Label: "Closed in period", Value: fmt.Sprintf("%d", closed)},
+			},
+			Tables: []reports.Table{table},
+		}, nil
+	}
+}
+
+// goldenPromptsReport summarizes the golden prompt runs of the period per
+// project and lists every regression they found
+func goldenPromptsReport(projectMgr *project.Manager, golden *goldenprompts.Manager) reports.Generator {
+	return func(ctx context.Context, req reports.Request) (*reports.Report, error) {
+		if projectMgr == nil {
+			return nil, fmt.Errorf("projects are not available")
+		}
+		only := req.Params["project_id"]
+
+		var runs []*goldenprompts.Run
+		found := false
+		for _, p := range projectMgr.ListProjects() {
+			if p == nil || (only != "" && p.ID != only) {
+				continue
+			}
+			found = true
+			list, err := golden.Runs(p.ID, 0)
+			if err != nil {
+				return nil, err
+			}
+			runs = append(runs, list...)
+		}
+		if only != "" && !found {
+			return nil, fmt.Errorf("project %s not found", only)
+		}
+		return goldenprompts.PeriodReport(runs, req.Start, req.End), nil
+	}
+}
@@ -0,0 +1,106 @@
+package loom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// maxRepairResponseLen bounds the responses sent for repair. Longer ones
+// are mostly file contents, which a cheap model is likely to mangle.
+const maxRepairResponseLen = 32 * 1024
+
+const responseRepairPrompt = `You fix malformed JSON produced by another model.
+Rewrite the response below as a single JSON document matching the given schema.
+Keep its meaning: do not add, drop or change actions, paths or contents, only fix the structure.
+Reply with the JSON document alone, without code fences or commentary.`
+
+// responseRepairer rewrites agent responses that do not parse, using the
+// configured provider or else the cheapest active one the project allows
+type responseRepairer struct {
+	registry   *provider.Registry
+	providerID string
+	serves     func(cfg *provider.ProviderConfig, projectID string) bool // nil allows every provider
+}
+
+// RepairResponse has response rewritten to match schema
+func (r *responseRepairer) RepairResponse(ctx context.Context, projectID, response string, schema *provider.JSONSchema, problems []string) (string, error) {
+	if len(response) > maxRepairResponseLen {
+		return "", fmt.Errorf("response too long to repair (%d bytes)", len(response))
+	}
+	providerID := r.pickProvider(projectID)
+	if providerID == "" {
+		return "", fmt.Errorf("no active provider may repair responses for project %s", projectID)
+	}
+
+	req := &provider.ChatCompletionRequest{
+		Messages: []provider.ChatMessage{
+			{Role: "system", Content: responseRepairPrompt},
+			{Role: "user", Content: fmt.Sprintf("Schema:\n%s\n\nProblems:\n- %s\n\nResponse:\n%s",
+				schema.Schema, strings.Join(problems, "\n- "), response)},
+		},
+		Temperature:    0,
+		ResponseFormat: provider.SchemaFormat(schema.Name, schema.Schema),
+	}
+	resp, err := r.registry.SendChatCompletion(ctx, providerID, req)
+	var formatErr *provider.ResponseFormatError
+	if errors.As(err, &formatErr) {
+		req.ResponseFormat = &provider.ResponseFormat{Type: "json_object"}
+		resp, err = r.registry.SendChatCompletion(ctx, providerID, req)
+	}
+	if err != nil {
+		return "", fmt.Errorf("repair via %s: %w", providerID, err)
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("repair via %s: empty response", providerID)
+	}
+	return stripCodeFence(resp.Choices[0].Message.Content), nil
+}
+
+// pickProvider returns the configured repair provider, or the active
+// provider with the lowest cost per token, if the project allows it
+func (r *responseRepairer) pickProvider(projectID string) string {
+	if r.providerID != "" {
+		if rp, err := r.registry.Get(r.providerID); err != nil || !r.allows(rp.Config, projectID) {
+			return ""
+		}
+		return r.providerID
+	}
+	var active []*provider.RegisteredProvider
+	for _, rp := range r.registry.ListActive() {
+		if r.allows(rp.Config, projectID) {
+			active = append(active, rp)
+		}
+	}
+	sort.SliceStable(active, func(i, j int) bool {
+		if active[i].Config.CostPerMToken != active[j].Config.CostPerMToken {
+			return active[i].Config.CostPerMToken < active[j].Config.CostPerMToken
+		}
+		return active[i].Config.ID < active[j].Config.ID
+	})
+	if len(active) == 0 {
+		return ""
+	}
+	return active[0].Config.ID
+}
+
+func (r *responseRepairer) allows(cfg *provider.ProviderConfig, projectID string) bool {
+	return r.serves == nil || r.serves(cfg, projectID)
+}
+
+// stripCodeFence removes a markdown code fence around content
+func stripCodeFence(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if nl := strings.IndexByte(content, '\n'); nl >= 0 {
+		content = content[nl+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
@@ -0,0 +1,57 @@
+package loom
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+func TestResponseRepairer_UsesCheapestProvider(t *testing.T) {
+	registry := provider.NewRegistry()
+	var requests []*provider.ChatCompletionRequest
+	cheap := provider.NewScriptedMockProvider(func(req *provider.ChatCompletionRequest) (string, bool) {
+		requests = append(requests, req)
+		return "```json\n{\"action\": \"done\"}\n```", true
+	})
+	costly := provider.NewScriptedMockProvider(func(req *provider.ChatCompletionRequest) (string, bool) {
+		t.Error("the costlier provider should not be asked to repair")
+		return "", false
+	})
+	registry.UpsertProtocol(&provider.ProviderConfig{ID: "costly", Status: "active", Model: "big", CostPerMToken: 10}, costly)
+	registry.UpsertProtocol(&provider.ProviderConfig{ID: "cheap", Status: "active", Model: "small", CostPerMToken: 0.5}, cheap)
+
+	r := &responseRepairer{registry: registry}
+	schema := &provider.JSONSchema{Name: "loom_action", Schema: []byte(`{"type": "object"}`)}
+	repaired, err := r.RepairResponse(context.Background(), "p1", `{"action": "done"`, schema, []string{"not valid JSON"})
+	if err != nil {
+		t.Fatalf("RepairResponse failed: %v", err)
+	}
+	if repaired != `{"action": "done"}` {
+		t.Errorf("repaired = %q, want the JSON without its code fence", repaired)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected one repair request, got %d", len(requests))
+	}
+	req := requests[0]
+	if req.ResponseFormat == nil || req.ResponseFormat.JSONSchema == nil || req.ResponseFormat.JSONSchema.Name != "loom_action" {
+		t.Errorf("expected the repair to be constrained to the schema, got %+v", req.ResponseFormat)
+	}
+	if !strings.Contains(req.Messages[1].Content, "- not valid JSON") {
+		t.Errorf("expected the problems in the repair prompt, got %q", req.Messages[1].Content)
+	}
+
+	if _, err := r.RepairResponse(context.Background(), "p1", strings.Repeat("x", maxRepairResponseLen+1), schema, nil); err == nil {
+		t.Error("expected an oversized response to be refused")
+	}
+
+	r.serves = func(cfg *provider.ProviderConfig, projectID string) bool { return cfg.ID != "cheap" }
+	if got := r.pickProvider("p1"); got != "costly" {
+		t.Errorf("pickProvider = %q, want the provider the project allows", got)
+	}
+	r.providerID = "cheap"
+	if got := r.pickProvider("p1"); got != "" {
+		t.Errorf("pickProvider = %q, want none when the configured provider may not serve the project", got)
+	}
+}
@@ -0,0 +1,60 @@
+package loom
+
+import (
+	"log"
+	"path/filepath"
+
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// newSandboxPolicy builds the command sandbox policy from the global and
+// per-project sandbox settings
+func newSandboxPolicy(cfg *config.Config) *executor.SandboxPolicy {
+	defaults := toExecutorSandbox(cfg.Sandbox)
+	if err := defaults.Validate(); err != nil {
+		log.Printf("[Sandbox] Invalid sandbox config, using local execution: %v", err)
+		defaults = executor.SandboxConfig{Mode: executor.SandboxModeLocal}
+	}
+
+	policy := executor.NewSandboxPolicy(defaults)
+	for _, p := range cfg.Projects {
+		if p.Sandbox == nil {
+			continue
+		}
+		if err := policy.SetProjectConfig(p.ID, toExecutorSandbox(*p.Sandbox)); err != nil {
+			log.Printf("[Sandbox] Ignoring sandbox override for project %s: %v", p.ID, err)
+		}
+	}
+	return policy
+}
+
+func toExecutorSandbox(c config.SandboxConfig) executor.SandboxConfig {
+	return executor.SandboxConfig{
+		Mode:          c.Mode,
+		Image:         c.Image,
+		CPUs:          c.CPUs,
+		Memory:        c.Memory,
+		Network:       c.Network,
+		MountPath:     c.MountPath,
+		ReadOnly:      c.ReadOnly,
+		Env:           c.Env,
+		FallbackLocal: c.FallbackLocal,
+	}
+}
+
+// commandWorkDir resolves a command's working directory against the
+// project worktree so sandboxed commands mount the right tree
+func (a *Loom) commandWorkDir(projectID, workingDir string) string {
+	if projectID == "" || a.projectManager == nil || filepath.IsAbs(workingDir) {
+		return workingDir
+	}
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil || p == nil || p.WorkDir == "" {
+		return workingDir
+	}
+	if workingDir == "" {
+		return p.WorkDir
+	}
+	return filepath.Join(p.WorkDir, workingDir)
+}
@@ -0,0 +1,121 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/scheduler"
+	temporalactivities "github.com/jordanhubbard/loom/internal/temporal/activities"
+	"github.com/jordanhubbard/loom/internal/temporal/workflows"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// Timer IDs are the IDs of the Temporal workflows they stand in for
+const (
+	loomHeartbeatTimer        = "loom-heartbeat-master"
+	loomHeartbeatInterval     = 10 * time.Second
+	providerHeartbeatInterval = 30 * time.Second
+)
+
+func providerHeartbeatTimer(providerID string) string {
+	return "provider-heartbeat-" + providerID
+}
+
+// newScheduler returns the embedded scheduler when cfg selects it. Its
+// timers are kept in db, so without a database nothing runs the workflows.
+func newScheduler(db *database.Database, cfg config.SchedulerConfig) *scheduler.Scheduler {
+	if cfg.Backend != "embedded" {
+		return nil
+	}
+	if db == nil {
+		log.Printf("[Scheduler] The embedded scheduler needs a database; heartbeats will not run")
+		return nil
+	}
+	return scheduler.New(db, cfg.PollInterval)
+}
+
+// GetScheduler returns the embedded scheduler, or nil when Temporal runs
+// the workflows
+func (a *Loom) GetScheduler() *scheduler.Scheduler {
+	return a.scheduler
+}
+
+// startScheduler registers the heartbeat activities with the embedded
+// scheduler, schedules the Ralph Loop and starts firing timers
+func (a *Loom) startScheduler(ctx context.Context) {
+	if a.scheduler == nil {
+		return
+	}
+	a.registerSchedulerActivities(a.config)
+
+	// As in the Temporal workflow, a new Ralph Loop beats at once
+	if err := a.scheduler.Schedule(loomHeartbeatTimer, "LoomHeartbeatActivity", nil, loomHeartbeatInterval, 0); err != nil {
+		log.Printf("[Scheduler] Failed to schedule the Ralph Loop: %v", err)
+	}
+	a.scheduler.Start(ctx)
+}
+
+// registerSchedulerActivities registers the heartbeat activities under the
+// policies in cfg, as the Temporal workflows schedule them
+func (a *Loom) registerSchedulerActivities(cfg *config.Config) {
+	loomActivities := temporalactivities.NewLoomActivities(a.database, a.dispatcher, a.beadsManager, a.agentManager)
+	a.scheduler.Register("LoomHeartbeatActivity", func(ctx context.Context, _ json.RawMessage, run int64) error {
+		return loomActivities.LoomHeartbeatActivity(ctx, int(run))
+	}, schedulerOptions(cfg, "LoomHeartbeatActivity"))
+
+	providerActivities := a.providerActivities()
+	a.scheduler.Register("ProviderHeartbeatActivity", func(ctx context.Context, input json.RawMessage, _ int64) error {
+		var in temporalactivities.ProviderHeartbeatInput
+		if err := json.Unmarshal(input, &in); err != nil {
+			return err
+		}
+		_, err := providerActivities.ProviderHeartbeatActivity(ctx, in)
+		return err
+	}, schedulerOptions(cfg, "ProviderHeartbeatActivity"))
+}
+
+// schedulerOptions converts an activity's policy to the embedded
+// scheduler's options. Heartbeat timeouts and non-retryable error types
+// apply only under Temporal.
+func schedulerOptions(cfg *config.Config, activity string) scheduler.ActivityOptions {
+	p := cfg.Temporal.ActivityPolicy(activity)
+	return scheduler.ActivityOptions{
+		Timeout:            p.StartToCloseTimeout,
+		MaxAttempts:        p.MaximumAttempts,
+		InitialInterval:    p.InitialInterval,
+		BackoffCoefficient: p.BackoffCoefficient,
+		MaximumInterval:    p.MaximumInterval,
+	}
+}
+
+func (a *Loom) providerActivities() *temporalactivities.ProviderActivities {
+	return temporalactivities.NewProviderActivities(a.providerRegistry, a.database, a.eventBus, a.modelCatalog, a.keyManager)
+}
+
+// runProviderQuery runs a REPL query as a Temporal workflow, or directly,
+// under the same policy, with the embedded scheduler
+func (a *Loom) runProviderQuery(ctx context.Context, input workflows.ProviderQueryWorkflowInput) (*temporalactivities.ProviderQueryResult, error) {
+	if a.temporalManager != nil {
+		return a.temporalManager.RunProviderQueryWorkflow(ctx, input)
+	}
+	activities := a.providerActivities()
+	var result *temporalactivities.ProviderQueryResult
+	err := scheduler.Execute(ctx, schedulerOptions(a.config, "ProviderQueryActivity"), func(ctx context.Context) error {
+		var err error
+		result, err = activities.ProviderQueryActivity(ctx, temporalactivities.ProviderQueryInput{
+			ProviderID:   input.ProviderID,
+			SystemPrompt: input.SystemPrompt,
+			Message:      input.Message,
+			Temperature:  input.Temperature,
+			MaxTokens:    input.MaxTokens,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
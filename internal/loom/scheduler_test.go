@@ -0,0 +1,60 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/scheduler"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+func TestEmbeddedScheduler(t *testing.T) {
+	l, tmpDir := testLoom(t, func(c *config.Config) {
+		c.Temporal.Host = "localhost:7233" // Ignored: the embedded scheduler replaces Temporal
+		c.Scheduler = config.SchedulerConfig{Backend: "embedded", PollInterval: time.Hour}
+	})
+	defer os.RemoveAll(tmpDir)
+	if l.GetTemporalManager() != nil || l.GetScheduler() == nil {
+		t.Fatal("the embedded backend should replace the Temporal manager")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	l.startScheduler(ctx)
+	defer l.scheduler.Close()
+
+	// A new Ralph Loop beats at once; wait for that beat so it cannot hold
+	// the timer when the test fires it
+	var timer *scheduler.Timer
+	var err error
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if timer, err = l.database.GetSchedulerTimer(loomHeartbeatTimer); err != nil || timer == nil || timer.Runs > 0 {
+			break
+		}
+	}
+	if err != nil || timer == nil || timer.Interval != loomHeartbeatInterval || timer.Runs != 1 {
+		t.Fatalf("Ralph Loop timer = %+v, %v", timer, err)
+	}
+	if err := l.scheduler.Trigger(loomHeartbeatTimer); err != nil {
+		t.Fatal(err)
+	}
+	if n := l.scheduler.RunDue(ctx); n != 1 {
+		t.Fatalf("RunDue fired %d timers, want the heartbeat", n)
+	}
+	if timer, _ = l.database.GetSchedulerTimer(loomHeartbeatTimer); timer.Runs != 2 || timer.LastError != "" {
+		t.Errorf("after a second beat: %+v", timer)
+	}
+
+	if err := l.ensureProviderHeartbeat(ctx, "p1"); err != nil {
+		t.Fatal(err)
+	}
+	if timer, _ := l.database.GetSchedulerTimer(providerHeartbeatTimer("p1")); timer == nil || timer.Interval != providerHeartbeatInterval {
+		t.Fatalf("provider heartbeat timer = %+v", timer)
+	}
+	_ = l.DeleteProvider(ctx, "p1")
+	if timer, _ := l.database.GetSchedulerTimer(providerHeartbeatTimer("p1")); timer != nil {
+		t.Error("deleting a provider should cancel its heartbeat")
+	}
+}
@@ -0,0 +1,88 @@
+package loom
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/beads"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/search"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+)
+
+// searchSubscriber is the event bus subscription reindexing beads whose
+// comments change
+const searchSubscriber = "search"
+
+// newSearch opens the full-text index in db. The database indexes
+// activities itself; beads are reindexed, with their comments, whenever
+// they or their comments change. Without a database there is no index.
+func newSearch(a *Loom, db *database.Database) *search.Manager {
+	if db == nil {
+		return nil
+	}
+	mgr := search.NewManager(db, &beadDocuments{loom: a, db: db})
+	a.beadsManager.SetChangeHook(mgr.BeadChanged)
+	if a.eventBus != nil {
+		sub := a.eventBus.Subscribe(searchSubscriber, func(event *eventbus.Event) bool {
+			return strings.HasPrefix(string(event.Type), "comment.")
+		})
+		go func() {
+			for event := range sub.Channel {
+				beadID, _ := event.Data["bead_id"].(string)
+				mgr.BeadChanged(beadID)
+			}
+		}()
+	}
+	return mgr
+}
+
+// closeSearch stops reindexing beads on comment events; closing the
+// subscription ends its goroutine
+func (a *Loom) closeSearch() {
+	if a.search != nil && a.eventBus != nil {
+		a.eventBus.Unsubscribe(searchSubscriber)
+	}
+}
+
+// GetSearch returns full-text search over activities and beads
+func (a *Loom) GetSearch() *search.Manager {
+	return a.search
+}
+
+// beadDocuments reads beads and their comments for the search index
+type beadDocuments struct {
+	loom *Loom
+	db   *database.Database
+}
+
+// BeadDocument returns nil for a bead that no longer exists, so it leaves
+// the index; other errors leave the index as it is
+func (b *beadDocuments) BeadDocument(beadID string) (*search.Document, error) {
+	bead, err := b.loom.beadsManager.GetBead(beadID)
+	if errors.Is(err, beads.ErrBeadNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if bead == nil {
+		return nil, nil
+	}
+	comments, err := b.db.GetCommentsByBeadID(beadID)
+	if err != nil {
+		return nil, err
+	}
+	body := []string{bead.Description}
+	for _, c := range comments {
+		body = append(body, c.Content)
+	}
+	return &search.Document{
+		OrgID:      b.loom.ProjectOrg(bead.ProjectID),
+		ProjectID:  bead.ProjectID,
+		ActorID:    bead.AssignedTo,
+		Title:      bead.Title,
+		Body:       strings.Join(body, "\n"),
+		OccurredAt: bead.UpdatedAt,
+	}, nil
+}
@@ -0,0 +1,145 @@
+package loom
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/observability"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Decision context keys for secrets overrides
+const (
+	secretsOverrideKey    = "secrets_override_fingerprint"
+	secretsOperationKey   = "secrets_operation"
+	secretsRequestedByKey = "secrets_requested_by"
+)
+
+// secretsGate connects the git secrets scanner to lessons and CEO decisions.
+// A block records a lesson so agents stop repeating the mistake and opens a
+// P0 decision; once the CEO approves it, the same findings are let through.
+type secretsGate struct {
+	loom *Loom
+}
+
+// secretsOverridePermission is what a human needs to approve an override
+const secretsOverridePermission = "decisions:admin"
+
+// SetDeciderAuthorizer sets how a human decider's authority is checked.
+// authorize reports whether the user holds permission, globally or for
+// projectID. Without one, any human decider is trusted, as when
+// authentication is off.
+func (a *Loom) SetDeciderAuthorizer(authorize func(userID, permission, projectID string) bool) {
+	a.deciderAuthorizer = authorize
+}
+
+// OverrideApproved reports whether a CEO decision approved these findings.
+// Approvals by agents, or by users without decisions:admin on the project,
+// do not count.
+func (g *secretsGate) OverrideApproved(projectID, fingerprint string) bool {
+	d := g.findOverrideDecision(projectID, fingerprint)
+	if d == nil || !strings.EqualFold(strings.TrimSpace(d.Decision), "approve") {
+		return false
+	}
+	if !g.authorizedDecider(d.DeciderID, projectID) {
+		observability.SecurityAudit("git.secrets_override_refused", map[string]interface{}{
+			"project_id":  projectID,
+			"fingerprint": fingerprint,
+			"decision_id": d.ID,
+			"approved_by": d.DeciderID,
+		})
+		return false
+	}
+	observability.SecurityAudit("git.secrets_override_used", map[string]interface{}{
+		"project_id":  projectID,
+		"fingerprint": fingerprint,
+		"decision_id": d.ID,
+		"approved_by": d.DeciderID,
+	})
+	return true
+}
+
+// authorizedDecider reports whether deciderID may approve an override: the
+// CEO, or a human user ("user-<id>") holding secretsOverridePermission
+func (g *secretsGate) authorizedDecider(deciderID, projectID string) bool {
+	if deciderID == "ceo" {
+		return true
+	}
+	userID, ok := strings.CutPrefix(deciderID, "user-")
+	if !ok || userID == "" {
+		return false
+	}
+	if g.loom.deciderAuthorizer == nil {
+		return true
+	}
+	return g.loom.deciderAuthorizer(userID, secretsOverridePermission, projectID)
+}
+
+// SecretsBlocked records the block and returns the override decision ID.
+// Repeated attempts with the same findings reuse the open decision.
+func (g *secretsGate) SecretsBlocked(projectID, beadID, agentID string, blocked *git.SecretsBlockedError) string {
+	a := g.loom
+	rules := make([]string, 0, len(blocked.Findings))
+	for _, f := range blocked.Findings {
+		rules = append(rules, fmt.Sprintf("%s:%d %s", f.File, f.Line, f.Rule))
+	}
+	observability.SecurityAudit("git.secrets_blocked", map[string]interface{}{
+		"project_id":  projectID,
+		"bead_id":     beadID,
+		"agent_id":    agentID,
+		"operation":   blocked.Operation,
+		"fingerprint": blocked.Fingerprint,
+		"findings":    rules,
+	})
+
+	if d := g.findOverrideDecision(projectID, blocked.Fingerprint); d != nil {
+		if d.Decision == "" {
+			return d.ID
+		}
+		// Denied: the secrets have to be removed
+		return ""
+	}
+
+	if a.lessonsProvider != nil {
+		detail := fmt.Sprintf("The secrets scanner blocked a %s containing: %s. Never commit credentials; read them from environment variables or the key manager, and keep fixtures obviously fake.",
+			blocked.Operation, strings.Join(rules, "; "))
+		if err := a.lessonsProvider.RecordLesson(projectID, "security", "Secrets blocked from commit", detail, beadID, agentID); err != nil {
+			log.Printf("[Secrets] Failed to record lesson: %v", err)
+		}
+	}
+
+	question := fmt.Sprintf("The secrets scanner blocked a %s in project %s (bead %s).\n\nFindings:\n- %s\n\nApprove only if these are known false positives or intentionally public values. Choose: approve | deny",
+		blocked.Operation, projectID, beadID, strings.Join(rules, "\n- "))
+	d, err := a.CreateDecisionBead(question, beadID, "system", []string{"approve", "deny"}, "deny", models.BeadPriorityP0, projectID)
+	if err != nil {
+		log.Printf("[Secrets] Failed to create override decision: %v", err)
+		return ""
+	}
+	_ = a.decisionManager.UpdateDecisionContext(d.ID, map[string]string{
+		secretsOverrideKey:    blocked.Fingerprint,
+		secretsOperationKey:   blocked.Operation,
+		secretsRequestedByKey: agentID,
+	})
+	return d.ID
+}
+
+// findOverrideDecision returns the most recent override decision for the
+// fingerprint, if any
+func (g *secretsGate) findOverrideDecision(projectID, fingerprint string) *models.DecisionBead {
+	decisions, err := g.loom.decisionManager.GetDecisionsByProject(projectID)
+	if err != nil {
+		return nil
+	}
+	var latest *models.DecisionBead
+	for _, d := range decisions {
+		if d.Context[secretsOverrideKey] != fingerprint {
+			continue
+		}
+		if latest == nil || d.CreatedAt.After(latest.CreatedAt) {
+			latest = d
+		}
+	}
+	return latest
+}
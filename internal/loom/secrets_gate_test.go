@@ -0,0 +1,103 @@
+package loom
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/git"
+)
+
+func TestSecretsGateOverrideFlow(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	defer os.RemoveAll(tmpDir)
+
+	gate := &secretsGate{loom: l}
+	blocked := &git.SecretsBlockedError{
+		Operation:   "commit",
+		Findings:    []git.SecretFinding{{File: "config.go", Line: 3, Rule: "api_key", Redacted: "sk-a****"}},
+		Fingerprint: "fp-1",
+	}
+
+	decisionID := gate.SecretsBlocked("proj-1", "", "agent-1", blocked)
+	if decisionID == "" {
+		t.Fatal("expected an override decision to be created")
+	}
+	if again := gate.SecretsBlocked("proj-1", "", "agent-1", blocked); again != decisionID {
+		t.Errorf("repeated block should reuse decision %s, got %s", decisionID, again)
+	}
+	if gate.OverrideApproved("proj-1", "fp-1") {
+		t.Error("override should not be approved before the CEO decides")
+	}
+
+	if l.database != nil {
+		lessons, err := l.database.GetLessonsForProject("proj-1", 10, 0)
+		if err != nil {
+			t.Fatalf("GetLessonsForProject failed: %v", err)
+		}
+		if len(lessons) != 1 || lessons[0].Category != "security" {
+			t.Errorf("expected one security lesson, got %+v", lessons)
+		}
+	}
+
+	if err := l.MakeDecision(decisionID, "user-ceo", "approve", "test fixture"); err != nil {
+		t.Fatalf("MakeDecision failed: %v", err)
+	}
+	if !gate.OverrideApproved("proj-1", "fp-1") {
+		t.Error("override should be approved after the CEO approves")
+	}
+	if gate.OverrideApproved("proj-2", "fp-1") {
+		t.Error("override should not apply to other projects")
+	}
+	if gate.OverrideApproved("proj-1", "fp-2") {
+		t.Error("override should not apply to other findings")
+	}
+}
+
+func TestSecretsGateOverrideRequiresAuthorizedDecider(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	defer os.RemoveAll(tmpDir)
+
+	gate := &secretsGate{loom: l}
+	block := func(fingerprint string) string {
+		t.Helper()
+		id := gate.SecretsBlocked("proj-1", "", "agent-1", &git.SecretsBlockedError{
+			Operation:   "push",
+			Findings:    []git.SecretFinding{{File: "main.go", Line: 1, Rule: "github_token"}},
+			Fingerprint: fingerprint,
+		})
+		if id == "" {
+			t.Fatalf("expected an override decision for %s", fingerprint)
+		}
+		return id
+	}
+
+	// An agent approving its own block does not count
+	if err := l.decisionManager.MakeDecision(block("fp-agent"), "agent-1", "approve", "looks fine"); err != nil {
+		t.Fatalf("MakeDecision failed: %v", err)
+	}
+	if gate.OverrideApproved("proj-1", "fp-agent") {
+		t.Error("an agent's approval should not unlock the push")
+	}
+
+	l.SetDeciderAuthorizer(func(userID, permission, projectID string) bool {
+		return userID == "admin" && permission == "decisions:admin" && projectID == "proj-1"
+	})
+	if err := l.MakeDecision(block("fp-viewer"), "user-viewer", "approve", "looks fine"); err != nil {
+		t.Fatalf("MakeDecision failed: %v", err)
+	}
+	if gate.OverrideApproved("proj-1", "fp-viewer") {
+		t.Error("a user without decisions:admin should not unlock the push")
+	}
+	if err := l.MakeDecision(block("fp-admin"), "user-admin", "approve", "test fixture"); err != nil {
+		t.Fatalf("MakeDecision failed: %v", err)
+	}
+	if !gate.OverrideApproved("proj-1", "fp-admin") {
+		t.Error("an authorized user's approval should unlock the push")
+	}
+	if err := l.decisionManager.MakeDecision(block("fp-ceo"), "ceo", "approve", "test fixture"); err != nil {
+		t.Fatalf("MakeDecision failed: %v", err)
+	}
+	if !gate.OverrideApproved("proj-1", "fp-ceo") {
+		t.Error("the CEO's approval should unlock the push")
+	}
+}
@@ -0,0 +1,136 @@
+package loom
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// sharedLessonCategory is the category of shared lessons created without
+// one
+const sharedLessonCategory = "insight"
+
+// sharedLessonOrg returns the organization whose shared lessons go into a
+// project's prompts, or "" when the project opted out or is unknown
+func (a *Loom) sharedLessonOrg(projectID string) string {
+	p, err := a.projectManager.GetProject(projectID)
+	if err != nil || p.SharedLessonsOptOut {
+		return ""
+	}
+	return org.Normalize(p.OrgID)
+}
+
+// PromoteLesson proposes a project lesson for its organization's shared
+// tier, optionally reworded to drop project specifics. The proposal goes
+// into other projects' prompts once approved; the project lesson stays.
+func (a *Loom) PromoteLesson(projectID, lessonID, title, detail string) (*models.Lesson, error) {
+	if a.database == nil || a.lessonsProvider == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	lessons, err := a.database.GetLessonsByID([]string{lessonID})
+	if err != nil {
+		return nil, err
+	}
+	if len(lessons) == 0 || lessons[0].ProjectID != projectID {
+		return nil, fmt.Errorf("lesson not found: %s", lessonID)
+	}
+	src := lessons[0]
+	orgID := a.ProjectOrg(projectID)
+	if existing, err := a.database.SharedLessonPromotedFrom(orgID, lessonID); err != nil {
+		return nil, err
+	} else if existing != nil {
+		return nil, fmt.Errorf("lesson %s was already promoted as %s", lessonID, existing.ID)
+	}
+
+	if title == "" {
+		title = src.Title
+	}
+	if detail == "" {
+		detail = src.Detail
+	}
+	shared := &models.Lesson{
+		ID:             uuid.New().String(),
+		OrgID:          orgID,
+		Category:       src.Category,
+		Title:          title,
+		Detail:         detail,
+		SourceBeadID:   src.SourceBeadID,
+		SourceAgentID:  src.SourceAgentID,
+		CreatedAt:      time.Now(),
+		RelevanceScore: 1.0,
+		ShareStatus:    models.ShareStatusProposed,
+		PromotedFrom:   src.ID,
+	}
+	if err := a.lessonsProvider.RecordSharedLesson(shared); err != nil {
+		return nil, err
+	}
+	return shared, nil
+}
+
+// CreateSharedLesson adds an approved lesson to an organization's shared
+// tier, for insights that were never a project's
+func (a *Loom) CreateSharedLesson(orgID, category, title, detail string) (*models.Lesson, error) {
+	if a.database == nil || a.lessonsProvider == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	if title == "" || detail == "" {
+		return nil, fmt.Errorf("title and detail are required")
+	}
+	if category == "" {
+		category = sharedLessonCategory
+	}
+	shared := &models.Lesson{
+		ID:             uuid.New().String(),
+		OrgID:          org.Normalize(orgID),
+		Category:       category,
+		Title:          title,
+		Detail:         detail,
+		CreatedAt:      time.Now(),
+		RelevanceScore: 1.0,
+		ShareStatus:    models.ShareStatusShared,
+	}
+	if err := a.lessonsProvider.RecordSharedLesson(shared); err != nil {
+		return nil, err
+	}
+	return shared, nil
+}
+
+// ListSharedLessons returns an organization's shared lessons in a status,
+// or in any status when it is empty, newest first
+func (a *Loom) ListSharedLessons(orgID string, status models.ShareStatus, limit int) ([]*models.Lesson, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	return a.database.ListSharedLessons(org.Normalize(orgID), status, limit)
+}
+
+// ApproveSharedLesson puts a proposed shared lesson into the prompts of
+// its organization's projects
+func (a *Loom) ApproveSharedLesson(orgID, lessonID string) (*models.Lesson, error) {
+	if a.database == nil {
+		return nil, fmt.Errorf("database not configured")
+	}
+	if err := a.database.ApproveSharedLesson(org.Normalize(orgID), lessonID); err != nil {
+		return nil, err
+	}
+	lessons, err := a.database.GetLessonsByID([]string{lessonID})
+	if err != nil {
+		return nil, err
+	}
+	if len(lessons) == 0 {
+		return nil, fmt.Errorf("shared lesson not found: %s", lessonID)
+	}
+	return lessons[0], nil
+}
+
+// DeleteSharedLesson rejects a proposed shared lesson or retires an
+// approved one
+func (a *Loom) DeleteSharedLesson(orgID, lessonID string) error {
+	if a.database == nil {
+		return fmt.Errorf("database not configured")
+	}
+	return a.database.DeleteSharedLesson(org.Normalize(orgID), lessonID)
+}
@@ -0,0 +1,270 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/analytics"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/git"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/prreview"
+	"github.com/jordanhubbard/loom/internal/reports"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// standupSources are what the standup report reads. Sources left nil are
+// left out of the report.
+type standupSources struct {
+	projects     func() []*models.Project
+	beads        func(projectID string) ([]*models.Bead, error)
+	commits      func(ctx context.Context, projectID, beadID string) ([]git.CommitMetadata, error)
+	pullRequests func(projectID string) ([]*prreview.Review, error)
+	logs         func(ctx context.Context, start, end time.Time) ([]*analytics.RequestLog, error)
+	lessons      func(projectID string) ([]*models.Lesson, error)
+}
+
+// registerStandupReport registers the daily standup report, read from the
+// beads, the projects' git history, pull request reviews, request logs and
+// lessons
+func registerStandupReport(mgr *reports.Manager, a *Loom, db *database.Database, gitRouter *actions.ProjectGitRouter) {
+	if mgr == nil {
+		return
+	}
+	src := standupSources{
+		projects: a.projectManager.ListProjects,
+		beads: func(projectID string) ([]*models.Bead, error) {
+			return a.beadsManager.ListBeads(map[string]interface{}{"project_id": projectID})
+		},
+		lessons: func(projectID string) ([]*models.Lesson, error) {
+			return db.GetLessonsForProject(projectID, 100, 0)
+		},
+	}
+	if gitRouter != nil {
+		src.commits = func(ctx context.Context, projectID, beadID string) ([]git.CommitMetadata, error) {
+			op, err := gitRouter.ForProject(projectID)
+			if err != nil {
+				return nil, err
+			}
+			result, err := op.GetBeadCommits(ctx, beadID)
+			if err != nil {
+				return nil, err
+			}
+			commits, _ := result["commits"].([]git.CommitMetadata)
+			return commits, nil
+		}
+	}
+	if a.prReviews != nil {
+		src.pullRequests = func(projectID string) ([]*prreview.Review, error) {
+			return a.prReviews.List(projectID, 0)
+		}
+	}
+	if storage, err := analytics.NewDatabaseStorage(db.DB()); err == nil {
+		src.logs = func(ctx context.Context, start, end time.Time) ([]*analytics.RequestLog, error) {
+			return storage.GetLogs(ctx, &analytics.LogFilter{StartTime: start, EndTime: end})
+		}
+	} else {
+		log.Printf("Warning: standup reports will leave out token and cost totals: %v", err)
+	}
+	mgr.RegisterGenerator(reports.ReportStandup, standupReport(src))
+}
+
+// standupDay is one project's day in a standup report
+type standupDay struct {
+	date, project                            string
+	opened, closed, commits, prs, escalation int
+	tokens                                   int64
+	costUSD                                  float64
+}
+
+// standupReport summarizes each project's days in the period: beads opened
+// and closed, the commits made for beads and the pull requests opened,
+// token and cost totals, escalations to the CEO and the lessons learned.
+// Days are those of the schedule's time zone. The project_id param limits
+// it to one project; a schedule of an organization other than the default
+// one covers that organization's projects alone.
+func standupReport(src standupSources) reports.Generator {
+	return func(ctx context.Context, req reports.Request) (*reports.Report, error) {
+		if src.projects == nil || src.beads == nil {
+			return nil, fmt.Errorf("projects are not available")
+		}
+		loc := req.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		in := func(t time.Time) bool { return !t.Before(req.Start) && t.Before(req.End) }
+		dateOf := func(t time.Time) string { return t.In(loc).Format("2006-01-02") }
+		only := req.Params["project_id"]
+
+		days := make(map[[2]string]*standupDay)
+		day := func(t time.Time, project string) *standupDay {
+			key := [2]string{dateOf(t), project}
+			if days[key] == nil {
+				days[key] = &standupDay{date: key[0], project: project}
+			}
+			return days[key]
+		}
+
+		escalations := reports.Table{Title: "Escalations", Columns: []string{"Date", "Project", "Bead", "Reason"}}
+		var lessons []*models.Lesson
+		names := make(map[string]string)
+		for _, p := range src.projects() {
+			if p == nil || (only != "" && p.ID != only) || (org.Confined(req.OrgID) && org.Normalize(p.OrgID) != req.OrgID) {
+				continue
+			}
+			names[p.ID] = p.ID
+			if p.Name != "" && p.Name != p.ID {
+				names[p.ID] = fmt.Sprintf("%s (%s)", p.Name, p.ID)
+			}
+			name := names[p.ID]
+
+			list, err := src.beads(p.ID)
+			if err != nil {
+				return nil, err
+			}
+			for _, b := range list {
+				if in(b.CreatedAt) {
+					day(b.CreatedAt, name).opened++
+				}
+				if b.ClosedAt != nil && in(*b.ClosedAt) {
+					day(*b.ClosedAt, name).closed++
+				}
+				if at, err := time.Parse(time.RFC3339, b.Context["escalated_to_ceo_at"]); err == nil && in(at) {
+					day(at, name).escalation++
+					escalations.Rows = append(escalations.Rows, []string{dateOf(at), name, b.ID + " " + b.Title, b.Context["escalated_to_ceo_reason"]})
+				}
+				// Only beads touched in the period can have commits in it
+				if src.commits == nil || b.UpdatedAt.Before(req.Start) {
+					continue
+				}
+				commits, err := src.commits(ctx, p.ID, b.ID)
+				if err != nil {
+					log.Printf("[Reports] Failed to read commits of bead %s: %v", b.ID, err)
+					continue
+				}
+				for _, c := range commits {
+					if in(c.Timestamp) {
+						day(c.Timestamp, name).commits++
+					}
+				}
+			}
+
+			if src.pullRequests != nil {
+				reviews, err := src.pullRequests(p.ID)
+				if err != nil {
+					return nil, err
+				}
+				// A pull request is reviewed once per push; count it on the
+				// day it was first reviewed
+				first := make(map[int]time.Time)
+				for _, r := range reviews {
+					if at, ok := first[r.PRNumber]; !ok || r.StartedAt.Before(at) {
+						first[r.PRNumber] = r.StartedAt
+					}
+				}
+				for _, at := range first {
+					if in(at) {
+						day(at, name).prs++
+					}
+				}
+			}
+
+			if src.lessons != nil {
+				list, err := src.lessons(p.ID)
+				if err != nil {
+					return nil, err
+				}
+				for _, l := range list {
+					if in(l.CreatedAt) {
+						lessons = append(lessons, l)
+					}
+				}
+			}
+		}
+		if only != "" && len(names) == 0 {
+			return nil, fmt.Errorf("project %s not found", only)
+		}
+
+		if src.logs != nil {
+			logs, err := src.logs(ctx, req.Start, req.End)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range logs {
+				name, ok := names[l.Metadata[analytics.MetadataProjectID]]
+				if !ok || !in(l.Timestamp) {
+					continue
+				}
+				d := day(l.Timestamp, name)
+				d.tokens += l.TotalTokens
+				d.costUSD += l.CostUSD
+			}
+		}
+
+		sorted := make([]*standupDay, 0, len(days))
+		for _, d := range days {
+			sorted = append(sorted, d)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			if sorted[i].date != sorted[j].date {
+				return sorted[i].date < sorted[j].date
+			}
+			return sorted[i].project < sorted[j].project
+		})
+		var total standupDay
+		summary := reports.Table{
+			Title:   "Daily Summary",
+			Columns: []string{"Date", "Project", "Opened", "Closed", "Commits", "Pull requests", "Tokens", "Cost (USD)", "Escalations"},
+		}
+		for _, d := range sorted {
+			summary.Rows = append(summary.Rows, []string{
+				d.date,
+				d.project,
+				fmt.Sprintf("%d", d.opened),
+				fmt.Sprintf("%d", d.closed),
+				fmt.Sprintf("%d", d.commits),
+				fmt.Sprintf("%d", d.prs),
+				fmt.Sprintf("%d", d.tokens),
+				fmt.Sprintf("%.2f", d.costUSD),
+				fmt.Sprintf("%d", d.escalation),
+			})
+			total.opened += d.opened
+			total.closed += d.closed
+			total.commits += d.commits
+			total.prs += d.prs
+			total.tokens += d.tokens
+			total.costUSD += d.costUSD
+			total.escalation += d.escalation
+		}
+		sort.SliceStable(escalations.Rows, func(i, j int) bool { return escalations.Rows[i][0] < escalations.Rows[j][0] })
+
+		// The lessons most relevant now are the notable ones
+		sort.Slice(lessons, func(i, j int) bool { return lessons[i].RelevanceScore > lessons[j].RelevanceScore })
+		if len(lessons) > reportTopN {
+			lessons = lessons[:reportTopN]
+		}
+		notable := reports.Table{Title: "Notable Lessons", Columns: []string{"Date", "Project", "Category", "Lesson"}}
+		for _, l := range lessons {
+			notable.Rows = append(notable.Rows, []string{dateOf(l.CreatedAt), names[l.ProjectID], strings.ReplaceAll(l.Category, "_", " "), l.Title})
+		}
+
+		return &reports.Report{
+			Title: "Daily Standup",
+			Summary: []reports.Metric{
+				{Label: "Beads opened", Value: fmt.Sprintf("%d", total.opened)},
+				{Label: "Beads closed", Value: fmt.Sprintf("%d", total.closed)},
+				{Label: "Commits", Value: fmt.Sprintf("%d", total.commits)},
+				{Label: "Pull requests", Value: fmt.Sprintf("%d", total.prs)},
+				{Label: "Tokens", Value: fmt.Sprintf("%d", total.tokens)},
+				{Label: "Cost", Value: fmt.Sprintf("$%.2f", total.costUSD)},
+				{Label: "Escalations", Value: fmt.Sprintf("%d", total.escalation)},
+			},
+			Tables: []reports.Table{summary, escalations, notable},
+		}, nil
+	}
+}
@@ -0,0 +1,124 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/staticanalysis"
+)
+
+const (
+	// staticAnalysisKey holds the JSON report of the latest static analysis
+	// run of a bead, whose findings its pull requests list
+	staticAnalysisKey = "static_analysis"
+	// maxAttachedFindings bounds the findings kept in bead context
+	maxAttachedFindings = 50
+	// prStaticAnalysisFindings bounds the findings listed in a pull request
+	prStaticAnalysisFindings = 20
+)
+
+// Analyze satisfies actions.StaticAnalyzer: it runs the static analysis
+// tools in the project's sandbox, by default those that apply to the
+// project, and attaches the report to the bead being worked.
+func (a *Loom) Analyze(ctx context.Context, actx actions.ActionContext, tools, files []string, timeoutSeconds int) (*staticanalysis.Report, error) {
+	p, err := a.projectManager.GetProject(actx.ProjectID)
+	if err != nil {
+		return nil, fmt.Errorf("project not found: %w", err)
+	}
+	if p.WorkDir == "" {
+		return nil, fmt.Errorf("project %s has no working copy to analyze", p.ID)
+	}
+	if len(tools) == 0 {
+		tools = staticanalysis.Detect(p.WorkDir)
+		if len(tools) == 0 {
+			return nil, fmt.Errorf("no static analysis tool applies to project %s; name one of %s", p.ID, strings.Join(staticanalysis.Tools, ", "))
+		}
+	}
+
+	roots := []string{p.WorkDir}
+	if a.shellExecutor != nil {
+		roots = append(roots, a.shellExecutor.GetSandboxPolicy().ForProject(p.ID).MountPath)
+	}
+	run := func(ctx context.Context, command string) (string, string, int, error) {
+		res, err := a.ExecuteShellCommand(ctx, executor.ExecuteCommandRequest{
+			AgentID:   actx.AgentID,
+			BeadID:    actx.BeadID,
+			ProjectID: p.ID,
+			Command:   command,
+			Timeout:   timeoutSeconds,
+		})
+		if err != nil {
+			return "", "", 0, err
+		}
+		exitCode := res.ExitCode
+		if strings.Contains(res.Error, "executable file not found") {
+			exitCode = 127
+		}
+		return res.Stdout, res.Stderr, exitCode, nil
+	}
+	report, err := staticanalysis.Run(ctx, run, p.WorkDir, tools, files, roots)
+	if err != nil {
+		return nil, err
+	}
+
+	if actx.BeadID != "" {
+		if err := a.attachStaticAnalysis(actx.BeadID, report); err != nil {
+			log.Printf("[StaticAnalysis] Failed to attach report to bead %s: %v", actx.BeadID, err)
+		}
+	}
+	audit.Record(audit.Event{
+		Actor:     actx.AgentID,
+		Action:    "project.static_analysis",
+		Resource:  p.ID,
+		ProjectID: p.ID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"bead_id": actx.BeadID,
+			"tools":   tools,
+			"counts":  report.Counts,
+		},
+	})
+	return report, nil
+}
+
+// attachStaticAnalysis keeps report, with its most severe findings, as the
+// bead's latest static analysis
+func (a *Loom) attachStaticAnalysis(beadID string, report *staticanalysis.Report) error {
+	attached := *report
+	if len(attached.Findings) > maxAttachedFindings {
+		attached.Truncated += len(attached.Findings) - maxAttachedFindings
+		attached.Findings = attached.Findings[:maxAttachedFindings]
+	}
+	data, err := json.Marshal(attached)
+	if err != nil {
+		return err
+	}
+	_, err = a.UpdateBead(beadID, map[string]interface{}{
+		"context": map[string]string{staticAnalysisKey: string(data)},
+	})
+	return err
+}
+
+// StaticAnalysisReport returns the latest static analysis report attached
+// to a bead, or nil when it has none
+func (a *Loom) StaticAnalysisReport(beadID string) (*staticanalysis.Report, error) {
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return nil, err
+	}
+	data := bead.Context[staticAnalysisKey]
+	if data == "" {
+		return nil, nil
+	}
+	var report staticanalysis.Report
+	if err := json.Unmarshal([]byte(data), &report); err != nil {
+		return nil, fmt.Errorf("invalid static analysis report on bead %s: %w", beadID, err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,71 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/staticanalysis"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestAttachStaticAnalysis(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	proj, err := l.CreateProject("lint", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	proj.WorkDir = t.TempDir()
+	bead, err := l.CreateBead("Fix the config loader", "", models.BeadPriorityP2, "task", proj.ID)
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	actx := actions.ActionContext{BeadID: bead.ID, ProjectID: proj.ID}
+
+	if _, err := l.Analyze(context.Background(), actx, nil, nil, 0); err == nil || !strings.Contains(err.Error(), "no static analysis tool applies") {
+		t.Errorf("analyzing a project with no Go or JS code = %v", err)
+	}
+	if notes := l.PullRequestNotes(context.Background(), actx); notes != "" {
+		t.Errorf("pull request notes before any analysis = %q", notes)
+	}
+
+	report := &staticanalysis.Report{
+		Tools:  []staticanalysis.ToolRun{{Tool: staticanalysis.ToolGosec, Findings: 60}},
+		Counts: map[string]int{staticanalysis.SeverityError: 60},
+	}
+	for i := 0; i < 60; i++ {
+		report.Findings = append(report.Findings, staticanalysis.Finding{
+			Tool: staticanalysis.ToolGosec, Rule: "G101", Severity: staticanalysis.SeverityError,
+			File: "config.go", Line: i + 1, Message: fmt.Sprintf("finding %d", i),
+		})
+	}
+	if err := l.attachStaticAnalysis(bead.ID, report); err != nil {
+		t.Fatalf("attachStaticAnalysis failed: %v", err)
+	}
+
+	attached, err := l.StaticAnalysisReport(bead.ID)
+	if err != nil || attached == nil {
+		t.Fatalf("StaticAnalysisReport = %v, %v", attached, err)
+	}
+	if len(attached.Findings) != maxAttachedFindings || attached.Truncated != 10 || attached.Counts[staticanalysis.SeverityError] != 60 {
+		t.Errorf("attached %d findings, %d truncated, counts %v", len(attached.Findings), attached.Truncated, attached.Counts)
+	}
+	if len(report.Findings) != 60 {
+		t.Errorf("attaching must not trim the agent's report, left %d findings", len(report.Findings))
+	}
+
+	notes := l.PullRequestNotes(context.Background(), actx)
+	if !strings.HasPrefix(notes, "## Static analysis\n\ngosec: 60 errors") || !strings.Contains(notes, "config.go:20") || strings.Contains(notes, "config.go:21 ") {
+		t.Errorf("pull request notes:\n%s", notes)
+	}
+	if !strings.Contains(notes, "... and 40 more") {
+		t.Errorf("pull request notes must count the findings left out:\n%s", notes)
+	}
+}
@@ -0,0 +1,166 @@
+package loom
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// UndoBeadResult reports what undoing a bead reverted
+type UndoBeadResult struct {
+	BeadID          string       `json:"bead_id"`
+	Branch          string       `json:"branch"` // Branch the reverts landed on
+	ClosedPRs       []int        `json:"closed_prs"`
+	DeletedBranches []string     `json:"deleted_branches"`
+	RevertedCommits []string     `json:"reverted_commits"` // Newest first, in the order they were reverted
+	Pushed          bool         `json:"pushed"`
+	Bead            *models.Bead `json:"bead"`
+}
+
+// UndoBead reverts everything a bead did in its project's repository: its
+// pull requests are closed, its branches deleted, and its commits still
+// on the checked-out branch reverted newest first. The branches and pull
+// requests are read from the audit log, the commits from their Bead
+// trailers. The undo is recorded on the bead, in the activity feed and as
+// a lesson for the project's agents.
+func (a *Loom) UndoBead(ctx context.Context, beadID, actor, reason string) (*UndoBeadResult, error) {
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return nil, fmt.Errorf("bead not found: %w", err)
+	}
+	if bead.Status == models.BeadStatusInProgress {
+		return nil, fmt.Errorf("bead %s is still in progress; wait for its agent to finish before undoing it", beadID)
+	}
+	if a.actionRouter == nil || a.actionRouter.Git == nil {
+		return nil, fmt.Errorf("git operator not configured")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		reason = fmt.Sprintf("undo of bead %s", beadID)
+	}
+
+	base := ""
+	if proj, err := a.projectManager.GetProject(bead.ProjectID); err == nil && proj != nil {
+		base = proj.Branch
+	}
+	branches, prs := a.beadGitHistory(bead.ProjectID, beadID)
+
+	undone, err := a.actionRouter.Git.UndoBead(actions.WithProjectID(ctx, bead.ProjectID), beadID, branches, prs, base, reason)
+	if err != nil {
+		audit.Record(audit.Event{
+			Actor:     actor,
+			Action:    "bead.undo",
+			Resource:  beadID,
+			ProjectID: bead.ProjectID,
+			Outcome:   audit.OutcomeFailure,
+			Details:   map[string]interface{}{"reason": reason, "error": err.Error()},
+		})
+		return nil, fmt.Errorf("failed to undo bead %s: %w", beadID, err)
+	}
+	result := &UndoBeadResult{BeadID: beadID}
+	result.Branch, _ = undone["branch"].(string)
+	result.ClosedPRs, _ = undone["closed_prs"].([]int)
+	result.DeletedBranches, _ = undone["deleted_branches"].([]string)
+	result.RevertedCommits, _ = undone["reverted_commits"].([]string)
+	result.Pushed, _ = undone["pushed"].(bool)
+
+	result.Bead, err = a.UpdateBead(beadID, map[string]interface{}{
+		"context": map[string]string{
+			"undone_by":   actor,
+			"undone_at":   time.Now().UTC().Format(time.RFC3339),
+			"undo_reason": reason,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record undo of bead %s: %w", beadID, err)
+	}
+
+	audit.Record(audit.Event{
+		Actor:     actor,
+		Action:    "bead.undo",
+		Resource:  beadID,
+		ProjectID: bead.ProjectID,
+		Outcome:   audit.OutcomeSuccess,
+		Details: map[string]interface{}{
+			"reason":           reason,
+			"closed_prs":       result.ClosedPRs,
+			"deleted_branches": result.DeletedBranches,
+			"reverted_commits": result.RevertedCommits,
+		},
+	})
+	if a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadUndone, beadID, bead.ProjectID, map[string]interface{}{
+			"title":            bead.Title,
+			"actor_id":         actor,
+			"actor_type":       "user",
+			"reason":           reason,
+			"closed_prs":       result.ClosedPRs,
+			"deleted_branches": result.DeletedBranches,
+			"reverted_commits": result.RevertedCommits,
+		})
+	}
+	if a.lessonsProvider != nil {
+		detail := fmt.Sprintf("The work of bead %s (%s) was undone by %s: %s. %d commit(s) were reverted, %d branch(es) deleted and %d pull request(s) closed; do not repeat the approach.",
+			beadID, bead.Title, actor, reason, len(result.RevertedCommits), len(result.DeletedBranches), len(result.ClosedPRs))
+		if err := a.lessonsProvider.RecordLesson(bead.ProjectID, "undo", "Bead work undone", detail, beadID, bead.AssignedTo); err != nil {
+			log.Printf("[Undo] Failed to record lesson: %v", err)
+		}
+	}
+	return result, nil
+}
+
+// beadGitHistory reads the branches a bead pushed and the pull requests it
+// opened, and has not closed since, from the audit log
+func (a *Loom) beadGitHistory(projectID, beadID string) (branches []string, prs []int) {
+	if a.auditLogger == nil {
+		return nil, nil
+	}
+	entries := func(action string) []*audit.Entry {
+		found, err := a.auditLogger.Query(audit.Filter{Action: action, ProjectID: projectID, Outcome: audit.OutcomeSuccess})
+		if err != nil {
+			log.Printf("[Undo] Failed to read %s from the audit log: %v", action, err)
+			return nil
+		}
+		var mine []*audit.Entry
+		for _, e := range found {
+			if id, _ := e.Details["bead_id"].(string); id == beadID && e.Resource != "" {
+				mine = append(mine, e)
+			}
+		}
+		return mine
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range entries("git.push") {
+		if !seen[e.Resource] {
+			seen[e.Resource] = true
+			branches = append(branches, e.Resource)
+		}
+	}
+
+	closed := make(map[int]bool)
+	for _, e := range entries("git.close_pr") {
+		if n, err := strconv.Atoi(strings.TrimPrefix(e.Resource, "#")); err == nil {
+			closed[n] = true
+		}
+	}
+	for _, e := range entries("git.create_pr") {
+		// The resource is the pull request URL, ending in its number
+		n, err := strconv.Atoi(path.Base(e.Resource))
+		if err != nil || closed[n] {
+			continue
+		}
+		closed[n] = true
+		prs = append(prs, n)
+	}
+	return branches, prs
+}
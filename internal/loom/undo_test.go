@@ -0,0 +1,76 @@
+package loom
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestBeadGitHistory(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	if l.auditLogger == nil {
+		t.Skip("audit log unavailable")
+	}
+
+	record := func(action, resource, beadID string) {
+		t.Helper()
+		if _, err := l.auditLogger.Record(audit.Event{
+			Actor:     "git",
+			Action:    action,
+			Resource:  resource,
+			ProjectID: "proj-1",
+			Outcome:   audit.OutcomeSuccess,
+			Details:   map[string]interface{}{"bead_id": beadID},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("git.push", "agent/bd-1/fix", "bd-1")
+	record("git.push", "agent/bd-1/fix", "bd-1")
+	record("git.push", "agent/bd-2/other", "bd-2")
+	record("git.create_pr", "https://github.com/o/r/pull/7", "bd-1")
+	record("git.create_pr", "https://github.com/o/r/pull/8", "bd-1")
+	record("git.create_pr", "https://github.com/o/r/pull/9", "bd-2")
+	record("git.close_pr", "#7", "bd-1")
+
+	branches, prs := l.beadGitHistory("proj-1", "bd-1")
+	if !reflect.DeepEqual(branches, []string{"agent/bd-1/fix"}) {
+		t.Errorf("branches = %v, want only the bead's pushed branch once", branches)
+	}
+	if !reflect.DeepEqual(prs, []int{8}) {
+		t.Errorf("pull requests = %v, want the bead's PR that is still open", prs)
+	}
+	if branches, prs := l.beadGitHistory("proj-2", "bd-1"); branches != nil || prs != nil {
+		t.Errorf("expected nothing from another project, got %v %v", branches, prs)
+	}
+}
+
+func TestUndoBead_RefusesBeadInProgress(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	proj, err := l.CreateProject("undo", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	bead, err := l.CreateBead("Busy", "", models.BeadPriorityP2, "task", proj.ID)
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	if _, err := l.UpdateBead(bead.ID, map[string]interface{}{"status": models.BeadStatusInProgress}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := l.UndoBead(context.Background(), bead.ID, "u-1", ""); err == nil {
+		t.Error("expected undoing a bead in progress to fail")
+	}
+	if _, err := l.UndoBead(context.Background(), "missing", "u-1", ""); err == nil {
+		t.Error("expected undoing a missing bead to fail")
+	}
+}
@@ -0,0 +1,172 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/executor"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	testpkg "github.com/jordanhubbard/loom/internal/testing"
+	"github.com/jordanhubbard/loom/internal/verification"
+	"github.com/jordanhubbard/loom/pkg/config"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Bead context keys written by verification
+const (
+	beadVerificationKey       = "verification"        // JSON of the last result
+	beadVerificationStatusKey = "verification_status" // Status of the last result
+	beadVerificationOfKey     = "verification_of"     // On a follow-up, the bead whose tests failed
+)
+
+// newVerifier builds the pre-close verifier from the global and per-project
+// verification settings. Tests run through the shell executor, so they run
+// in the project's sandbox.
+func newVerifier(a *Loom, cfg *config.Config) *verification.Verifier {
+	v := verification.NewVerifier(&beadTestRunner{loom: a}, toVerificationConfig(cfg.Verification))
+	for _, p := range cfg.Projects {
+		if p.Verification == nil {
+			continue
+		}
+		if err := v.SetProjectConfig(p.ID, toVerificationConfig(*p.Verification)); err != nil {
+			log.Printf("[Verification] Ignoring verification override for project %s: %v", p.ID, err)
+		}
+	}
+	return v
+}
+
+func toVerificationConfig(c config.VerificationConfig) verification.Config {
+	return verification.Config{
+		Enabled:           c.Enabled,
+		Command:           c.Command,
+		CoverageThreshold: c.CoverageThreshold,
+		BeadTypes:         c.BeadTypes,
+		Timeout:           c.Timeout,
+	}
+}
+
+// GetVerifier returns the pre-close test verifier
+func (a *Loom) GetVerifier() *verification.Verifier {
+	return a.verifier
+}
+
+// VerifyBead runs the bead's project tests and attaches the result to the
+// bead. A failed verification files a follow-up bead to fix it, reusing the
+// one filed by an earlier failure while that is still open.
+func (a *Loom) VerifyBead(ctx context.Context, beadID string) (*verification.Result, error) {
+	if a.verifier == nil {
+		return nil, fmt.Errorf("verification not configured")
+	}
+	bead, err := a.beadsManager.GetBead(beadID)
+	if err != nil {
+		return nil, fmt.Errorf("bead not found: %w", err)
+	}
+
+	res := a.verifier.Verify(ctx, bead.ProjectID, beadID)
+	if !res.Passed {
+		followUp, err := a.verificationFollowUp(bead, res)
+		if err != nil {
+			log.Printf("[Verification] Failed to file follow-up bead for %s: %v", beadID, err)
+		} else {
+			res.FollowUpBeadID = followUp
+		}
+	}
+
+	resultJSON, err := json.Marshal(res)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode verification result: %w", err)
+	}
+	if err := a.beadsManager.UpdateBead(beadID, map[string]interface{}{
+		"context": map[string]string{
+			beadVerificationKey:       string(resultJSON),
+			beadVerificationStatusKey: res.Status,
+		},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to attach verification result: %w", err)
+	}
+
+	if !res.Passed && a.eventBus != nil {
+		_ = a.eventBus.PublishBeadEvent(eventbus.EventTypeBeadVerifyFailed, beadID, bead.ProjectID, map[string]interface{}{
+			"title":             bead.Title,
+			"status":            res.Status,
+			"summary":           res.Summary(),
+			"command":           res.Command,
+			"failed_tests":      res.FailedTests,
+			"follow_up_bead_id": res.FollowUpBeadID,
+		})
+	}
+	return res, nil
+}
+
+// verificationFollowUp files a bead to fix a failed verification, or
+// returns the follow-up an earlier failure filed if it is still open
+func (a *Loom) verificationFollowUp(bead *models.Bead, res *verification.Result) (string, error) {
+	var previous verification.Result
+	if err := json.Unmarshal([]byte(bead.Context[beadVerificationKey]), &previous); err == nil && previous.FollowUpBeadID != "" {
+		if existing, err := a.beadsManager.GetBead(previous.FollowUpBeadID); err == nil && existing.Status != models.BeadStatusClosed {
+			return existing.ID, nil
+		}
+	}
+
+	var desc strings.Builder
+	fmt.Fprintf(&desc, "Verification of %s (%s) failed: %s\n\nCommand: %s\n", bead.ID, bead.Title, res.Summary(), res.Command)
+	if len(res.FailedTests) > 0 {
+		desc.WriteString("\nFailed tests:\n")
+		for _, name := range res.FailedTests {
+			fmt.Fprintf(&desc, "- %s\n", name)
+		}
+	}
+	if res.Output != "" {
+		fmt.Fprintf(&desc, "\nOutput:\n```\n%s\n```\n", strings.TrimSpace(res.Output))
+	}
+
+	followUp, err := a.CreateBead("Fix failing tests for "+bead.Title, desc.String(), models.BeadPriorityP1, "bug", bead.ProjectID)
+	if err != nil {
+		return "", err
+	}
+	if err := a.beadsManager.UpdateBead(followUp.ID, map[string]interface{}{
+		"context": map[string]string{beadVerificationOfKey: bead.ID},
+	}); err != nil {
+		log.Printf("[Verification] Failed to link follow-up bead %s to %s: %v", followUp.ID, bead.ID, err)
+	}
+	return followUp.ID, nil
+}
+
+// beadTestRunner runs verification commands through the shell executor in
+// the project's work directory
+type beadTestRunner struct {
+	loom *Loom
+}
+
+// Run runs command and returns its stdout and stderr together
+func (r *beadTestRunner) Run(ctx context.Context, projectID, beadID, command string) (string, int, error) {
+	res, err := r.loom.ExecuteShellCommand(ctx, executor.ExecuteCommandRequest{
+		AgentID:   "verification",
+		BeadID:    beadID,
+		ProjectID: projectID,
+		Command:   command,
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	output := res.Stdout
+	if res.Stderr != "" {
+		output += "\n" + res.Stderr
+	}
+	if res.ExitCode < 0 {
+		return output, res.ExitCode, fmt.Errorf("%s", res.Error)
+	}
+	return output, res.ExitCode, nil
+}
+
+// Framework detects the test framework from the project's work directory
+func (r *beadTestRunner) Framework(projectID string) (string, error) {
+	workDir := r.loom.commandWorkDir(projectID, "")
+	if workDir == "" {
+		return "", fmt.Errorf("project %s has no work directory", projectID)
+	}
+	return testpkg.NewTestRunner(workDir).DetectFramework(workDir)
+}
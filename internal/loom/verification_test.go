@@ -0,0 +1,97 @@
+package loom
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/verification"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// scriptedTestRunner reports the next scripted outcome on each run
+type scriptedTestRunner struct {
+	outputs   []string
+	exitCodes []int
+	runs      int
+}
+
+func (s *scriptedTestRunner) Run(ctx context.Context, projectID, beadID, command string) (string, int, error) {
+	i := s.runs
+	s.runs++
+	return s.outputs[i], s.exitCodes[i], nil
+}
+
+func (s *scriptedTestRunner) Framework(projectID string) (string, error) {
+	return "go", nil
+}
+
+func TestCloseBead_RequiresPassingVerification(t *testing.T) {
+	l, tmpDir := testLoom(t)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+	l.beadsManager.SetBeadsPath(filepath.Join(t.TempDir(), ".beads"))
+
+	runner := &scriptedTestRunner{
+		outputs:   []string{"--- FAIL: TestLogin (0.00s)\nFAIL\n", "--- FAIL: TestLogin (0.00s)\nFAIL\n", "ok  \texample.com/app\t0.1s\n"},
+		exitCodes: []int{1, 1, 0},
+	}
+	l.verifier = verification.NewVerifier(runner, verification.Config{Enabled: true, BeadTypes: []string{"task"}})
+
+	proj, err := l.CreateProject("verification", ".", "", "", nil)
+	if err != nil {
+		t.Fatalf("CreateProject failed: %v", err)
+	}
+	bead, err := l.CreateBead("Add login", "desc", models.BeadPriorityP2, "task", proj.ID)
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+
+	if err := l.CloseBead(bead.ID, "done"); err == nil {
+		t.Fatal("expected failing tests to keep the bead open")
+	}
+	got, _ := l.beadsManager.GetBead(bead.ID)
+	if got.Status == models.BeadStatusClosed || got.Context[beadVerificationStatusKey] != verification.StatusFailed {
+		t.Fatalf("expected an open bead with a failed verification, got %s %v", got.Status, got.Context)
+	}
+	var res verification.Result
+	if err := json.Unmarshal([]byte(got.Context[beadVerificationKey]), &res); err != nil {
+		t.Fatalf("verification result not attached: %v", err)
+	}
+	if len(res.FailedTests) != 1 || res.FailedTests[0] != "TestLogin" || res.FollowUpBeadID == "" {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	followUp, err := l.beadsManager.GetBead(res.FollowUpBeadID)
+	if err != nil {
+		t.Fatalf("follow-up bead not filed: %v", err)
+	}
+	if followUp.Type != "bug" || followUp.Context[beadVerificationOfKey] != bead.ID {
+		t.Errorf("unexpected follow-up %+v", followUp)
+	}
+
+	// A second failure reuses the open follow-up
+	if err := l.CloseBead(bead.ID, "done"); err == nil {
+		t.Fatal("expected the second failure to keep the bead open")
+	}
+	got, _ = l.beadsManager.GetBead(bead.ID)
+	var again verification.Result
+	_ = json.Unmarshal([]byte(got.Context[beadVerificationKey]), &again)
+	if again.FollowUpBeadID != res.FollowUpBeadID {
+		t.Errorf("expected follow-up %s to be reused, got %s", res.FollowUpBeadID, again.FollowUpBeadID)
+	}
+
+	if err := l.CloseBead(bead.ID, "done"); err != nil {
+		t.Fatalf("CloseBead failed after the tests passed: %v", err)
+	}
+	got, _ = l.beadsManager.GetBead(bead.ID)
+	if got.Status != models.BeadStatusClosed || got.Context["close_reason"] != "done" || got.Context[beadVerificationStatusKey] != verification.StatusPassed {
+		t.Errorf("expected a closed, verified bead, got %s %v", got.Status, got.Context)
+	}
+
+	// Types outside the configured ones close without a run
+	decision, _ := l.CreateBead("Pick a database", "desc", models.BeadPriorityP2, "decision", proj.ID)
+	if err := l.CloseBead(decision.ID, "sqlite"); err != nil || runner.runs != 3 {
+		t.Errorf("expected the decision to close unverified, got %v after %d runs", err, runner.runs)
+	}
+}
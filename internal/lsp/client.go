@@ -0,0 +1,191 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// errClientClosed is returned for calls on a connection that has ended
+var errClientClosed = errors.New("language server connection closed")
+
+// Client is a JSON-RPC connection to a language server. Requests the
+// server makes of the client are answered with empty results.
+type Client struct {
+	conn io.ReadWriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan *message
+	err     error // Why the connection ended
+
+	done chan struct{}
+}
+
+// NewClient starts reading responses from conn
+func NewClient(conn io.ReadWriteCloser) *Client {
+	c := &Client{
+		conn:    conn,
+		pending: make(map[int64]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(conn))
+	return c
+}
+
+// Call sends a request and decodes its result into result, which may be nil
+func (c *Client) Call(ctx context.Context, method string, params, result interface{}) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan *message, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	raw, err := encodeParams(params)
+	if err != nil {
+		return err
+	}
+	rawID := json.RawMessage(strconv.FormatInt(id, 10))
+	if err := c.send(&message{JSONRPC: "2.0", ID: &rawID, Method: method, Params: raw}); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		_ = c.Notify("$/cancelRequest", map[string]interface{}{"id": id})
+		return ctx.Err()
+	case <-c.done:
+		return c.Err()
+	case resp := <-ch:
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	}
+}
+
+// Notify sends a notification, which has no response
+func (c *Client) Notify(method string, params interface{}) error {
+	raw, err := encodeParams(params)
+	if err != nil {
+		return err
+	}
+	return c.send(&message{JSONRPC: "2.0", Method: method, Params: raw})
+}
+
+// Done is closed when the connection ends
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns why the connection ended, or nil while it is open
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Close ends the connection
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	c.fail(errClientClosed)
+	return err
+}
+
+func (c *Client) send(msg *message) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if err := writeMessage(c.conn, msg); err != nil {
+		c.fail(fmt.Errorf("failed to write to language server: %w", err))
+		return c.Err()
+	}
+	return nil
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	for {
+		msg, err := readMessage(r)
+		if err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) {
+				err = errClientClosed
+			}
+			c.fail(err)
+			return
+		}
+		switch {
+		case msg.Method != "" && msg.ID != nil:
+			go c.reply(msg)
+		case msg.Method != "":
+			// Notifications such as diagnostics and log messages are not used
+		case msg.ID != nil:
+			id, err := strconv.ParseInt(string(*msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch := c.pending[id]
+			c.mu.Unlock()
+			if ch != nil {
+				ch <- msg
+			}
+		}
+	}
+}
+
+// reply answers a request from the server. workspace/configuration expects
+// one result per requested item; everything else accepts null.
+func (c *Client) reply(req *message) {
+	result := json.RawMessage("null")
+	if req.Method == "workspace/configuration" {
+		var params struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		_ = json.Unmarshal(req.Params, &params)
+		result, _ = json.Marshal(make([]interface{}, len(params.Items)))
+	}
+	_ = c.send(&message{JSONRPC: "2.0", ID: req.ID, Result: result})
+}
+
+// fail records why the connection ended and wakes pending calls
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.done)
+}
+
+// encodeParams encodes the params of a message, leaving nil out
+func encodeParams(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("cannot encode %T: %w", v, err)
+	}
+	return data, nil
+}
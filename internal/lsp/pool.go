@@ -0,0 +1,140 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// DefaultIdleTimeout is how long a project's language servers run unused
+// before they are stopped
+const DefaultIdleTimeout = 15 * time.Minute
+
+// Pool keeps one LSPService per project, so each project's language
+// servers are started on first use and stopped when the project has not
+// used them for the idle timeout
+type Pool struct {
+	idleTimeout time.Duration
+	commands    map[string][]string // Overrides of DefaultServers
+
+	mu       sync.Mutex
+	services map[string]*pooledService // Project ID -> service
+}
+
+type pooledService struct {
+	service  *LSPService
+	root     string
+	lastUsed time.Time
+}
+
+// NewPool creates a pool whose servers stop after idleTimeout unused
+// (DefaultIdleTimeout when 0). commands overrides the command lines of
+// DefaultServers by language.
+func NewPool(idleTimeout time.Duration, commands map[string][]string) *Pool {
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultIdleTimeout
+	}
+	return &Pool{
+		idleTimeout: idleTimeout,
+		commands:    commands,
+		services:    make(map[string]*pooledService),
+	}
+}
+
+// Service returns the service of a project checked out in root, replacing
+// it when the project moved
+func (p *Pool) Service(projectID, root string) (*LSPService, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ps, ok := p.services[projectID]; ok {
+		if ps.root == root {
+			ps.lastUsed = time.Now()
+			return ps.service, nil
+		}
+		go ps.service.Close()
+		delete(p.services, projectID)
+	}
+
+	service, err := NewLSPService(root)
+	if err != nil {
+		return nil, err
+	}
+	for language, command := range p.commands {
+		service.commands[language] = command
+	}
+	p.services[projectID] = &pooledService{service: service, root: root, lastUsed: time.Now()}
+	return service, nil
+}
+
+// Servers describes the running language servers of a project
+func (p *Pool) Servers(projectID string) []ServerStatus {
+	p.mu.Lock()
+	ps, ok := p.services[projectID]
+	p.mu.Unlock()
+	if !ok {
+		return []ServerStatus{}
+	}
+	return ps.service.Servers()
+}
+
+// Stop stops the language servers of a project; the next request starts
+// them again
+func (p *Pool) Stop(projectID string) error {
+	p.mu.Lock()
+	ps, ok := p.services[projectID]
+	delete(p.services, projectID)
+	p.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return ps.service.Close()
+}
+
+// Reap stops the servers of projects idle for longer than the idle
+// timeout and returns how many projects it stopped
+func (p *Pool) Reap(now time.Time) int {
+	p.mu.Lock()
+	var idle []*LSPService
+	for id, ps := range p.services {
+		if now.Sub(ps.lastUsed) >= p.idleTimeout {
+			idle = append(idle, ps.service)
+			delete(p.services, id)
+		}
+	}
+	p.mu.Unlock()
+	for _, service := range idle {
+		_ = service.Close()
+	}
+	return len(idle)
+}
+
+// Run reaps idle projects until ctx is done, then stops every server
+func (p *Pool) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			_ = p.Close()
+			return
+		case now := <-ticker.C:
+			p.Reap(now)
+		}
+	}
+}
+
+// Close stops the language servers of every project
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	services := p.services
+	p.services = make(map[string]*pooledService)
+	p.mu.Unlock()
+	var errs []error
+	for _, ps := range services {
+		if err := ps.service.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
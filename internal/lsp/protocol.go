@@ -0,0 +1,237 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// The subset of the Language Server Protocol the service speaks. Positions
+// are zero-based, with characters counted in UTF-16 code units.
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type textEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type textDocumentEdit struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Edits        []textEdit             `json:"edits"`
+	Kind         string                 `json:"kind,omitempty"` // Set on create, rename and delete file operations
+}
+
+type workspaceEdit struct {
+	Changes         map[string][]textEdit `json:"changes,omitempty"`
+	DocumentChanges []textDocumentEdit    `json:"documentChanges,omitempty"`
+}
+
+// message is a JSON-RPC 2.0 request, notification or response
+type message struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  json.RawMessage  `json:"result,omitempty"`
+	Error   *rpcError        `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("language server error %d: %s", e.Code, e.Message)
+}
+
+// writeMessage frames msg with the Content-Length header LSP uses
+func writeMessage(w io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+// readMessage reads one framed message
+func readMessage(r *bufio.Reader) (*message, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length <= 0 {
+		return nil, fmt.Errorf("invalid Content-Length %q", header.Get("Content-Length"))
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	var msg message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return &msg, nil
+}
+
+// fileURI returns the file:// URI of an absolute path
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// uriPath returns the path of a file:// URI
+func uriPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI %s", uri)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
+
+// lineText returns line n (zero-based) of content, without its newline
+func lineText(content string, n int) string {
+	for i := 0; i < n; i++ {
+		nl := strings.IndexByte(content, '\n')
+		if nl < 0 {
+			return ""
+		}
+		content = content[nl+1:]
+	}
+	if nl := strings.IndexByte(content, '\n'); nl >= 0 {
+		content = content[:nl]
+	}
+	return strings.TrimSuffix(content, "\r")
+}
+
+// utf16Offset converts a zero-based rune column of line to UTF-16 units
+func utf16Offset(line string, column int) int {
+	units := 0
+	for i, r := range []rune(line) {
+		if i == column {
+			break
+		}
+		units += utf16.RuneLen(r)
+	}
+	return units
+}
+
+// runeColumn converts a UTF-16 offset within line to a zero-based rune column
+func runeColumn(line string, character int) int {
+	units, column := 0, 0
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	return column
+}
+
+// byteOffset returns the offset in content of an LSP position
+func byteOffset(content string, p position) (int, error) {
+	offset := 0
+	for i := 0; i < p.Line; i++ {
+		nl := strings.IndexByte(content[offset:], '\n')
+		if nl < 0 {
+			return 0, fmt.Errorf("line %d is past the end of the file", p.Line+1)
+		}
+		offset += nl + 1
+	}
+	line := content[offset:]
+	if nl := strings.IndexByte(line, '\n'); nl >= 0 {
+		line = line[:nl]
+	}
+	units := 0
+	for i, r := range line {
+		if units >= p.Character {
+			return offset + i, nil
+		}
+		units += utf16.RuneLen(r)
+	}
+	return offset + len(line), nil
+}
+
+// symbolPosition finds symbol as a whole word on line (1-indexed), or on
+// the first line that has it when line is 0, and returns its 1-indexed
+// line and rune column
+func symbolPosition(content string, line int, symbol string) (int, int, bool) {
+	lines := strings.Split(content, "\n")
+	for i, text := range lines {
+		if line > 0 && i != line-1 {
+			continue
+		}
+		for from := 0; ; {
+			idx := strings.Index(text[from:], symbol)
+			if idx < 0 {
+				break
+			}
+			start := from + idx
+			end := start + len(symbol)
+			if !identBefore(text, start) && !identAt(text, end) {
+				return i + 1, utf8.RuneCountInString(text[:start]) + 1, true
+			}
+			from = start + 1
+		}
+	}
+	return 0, 0, false
+}
+
+func identBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return isIdentRune(r)
+}
+
+func identAt(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return isIdentRune(r)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r > utf8.RuneSelf
+}
@@ -2,7 +2,35 @@ package lsp
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultServers are the language server command lines started for each
+// language, run in the project's working copy and spoken to over stdio
+var DefaultServers = map[string][]string{
+	"go":         {"gopls", "serve"},
+	"typescript": {"typescript-language-server", "--stdio"},
+	"javascript": {"typescript-language-server", "--stdio"},
+	"python":     {"pylsp"},
+}
+
+const (
+	// initializeTimeout bounds a server's start-up, which for gopls includes
+	// loading the module
+	initializeTimeout = 2 * time.Minute
+	// shutdownTimeout bounds the orderly shutdown of a server before it is
+	// killed
+	shutdownTimeout = 5 * time.Second
 )
 
 // Location represents a code location with file, line, and column
@@ -23,152 +51,543 @@ type Symbol struct {
 // LSPService provides code navigation capabilities using language servers
 type LSPService struct {
 	projectPath string
-	servers     map[string]*LanguageServer // language -> server
+
+	mu       sync.Mutex                 // Serializes requests, which share the open documents
+	servers  map[string]*LanguageServer // language -> server
+	commands map[string][]string
+
+	// connect starts a language server and returns its stdio; replaced in
+	// tests
+	connect func(command []string, root string) (io.ReadWriteCloser, int, error)
 }
 
 // LanguageServer represents a language server process
 type LanguageServer struct {
-	Language string
-	Command  string
-	Args     []string
-	// Process management fields would go here
+	Language  string
+	Command   string
+	Args      []string
+	PID       int
+	StartedAt time.Time
+
+	client *Client
+	docs   map[string]*openDocument // Absolute path -> document the server has open
+}
+
+type openDocument struct {
+	version int
+	content string
+}
+
+// ServerStatus describes a running language server
+type ServerStatus struct {
+	Language  string    `json:"language"`
+	Command   string    `json:"command"`
+	PID       int       `json:"pid,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	OpenFiles int       `json:"open_files"`
 }
 
 // NewLSPService creates a new LSP service
 func NewLSPService(projectPath string) (*LSPService, error) {
+	abs, err := filepath.Abs(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	commands := make(map[string][]string, len(DefaultServers))
+	for language, command := range DefaultServers {
+		commands[language] = command
+	}
 	return &LSPService{
-		projectPath: projectPath,
+		projectPath: abs,
 		servers:     make(map[string]*LanguageServer),
+		commands:    commands,
+		connect:     startProcess,
 	}, nil
 }
 
+// SetServerCommand overrides the command line of a language's server; an
+// empty command disables the language
+func (s *LSPService) SetServerCommand(language string, command []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commands[language] = command
+}
+
 // FindReferences finds all references to a symbol
 func (s *LSPService) FindReferences(ctx context.Context, req FindReferencesRequest) ([]Location, error) {
-	// Determine language from file extension
-	language := detectLanguage(req.File)
-
-	// Ensure language server is running
-	if err := s.ensureServer(language); err != nil {
-		return nil, fmt.Errorf("failed to start language server: %w", err)
-	}
-
-	// Send LSP textDocument/references request
-	// This is a placeholder - full LSP implementation would go here
-	locations, err := s.sendReferencesRequest(ctx, req)
+	var raw json.RawMessage
+	err := s.request(ctx, req.File, req.Line, req.Column, req.Symbol, func(srv *LanguageServer, doc textDocumentPositionParams) error {
+		return srv.client.Call(ctx, "textDocument/references", map[string]interface{}{
+			"textDocument": doc.TextDocument,
+			"position":     doc.Position,
+			"context":      map[string]bool{"includeDeclaration": true},
+		}, &raw)
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return locations, nil
+	return s.locations(raw)
 }
 
 // GoToDefinition finds the definition of a symbol
 func (s *LSPService) GoToDefinition(ctx context.Context, req GoToDefinitionRequest) (*Location, error) {
-	language := detectLanguage(req.File)
-
-	if err := s.ensureServer(language); err != nil {
-		return nil, fmt.Errorf("failed to start language server: %w", err)
-	}
-
-	// Send LSP textDocument/definition request
-	location, err := s.sendDefinitionRequest(ctx, req)
+	var raw json.RawMessage
+	err := s.request(ctx, req.File, req.Line, req.Column, req.Symbol, func(srv *LanguageServer, doc textDocumentPositionParams) error {
+		return srv.client.Call(ctx, "textDocument/definition", doc, &raw)
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return location, nil
+	locations, err := s.locations(raw)
+	if err != nil || len(locations) == 0 {
+		return nil, err
+	}
+	return &locations[0], nil
 }
 
 // FindImplementations finds all implementations of an interface/abstract method
 func (s *LSPService) FindImplementations(ctx context.Context, req FindImplementationsRequest) ([]Location, error) {
-	language := detectLanguage(req.File)
-
-	if err := s.ensureServer(language); err != nil {
-		return nil, fmt.Errorf("failed to start language server: %w", err)
+	var raw json.RawMessage
+	err := s.request(ctx, req.File, req.Line, req.Column, req.Symbol, func(srv *LanguageServer, doc textDocumentPositionParams) error {
+		return srv.client.Call(ctx, "textDocument/implementation", doc, &raw)
+	})
+	if err != nil {
+		return nil, err
 	}
+	return s.locations(raw)
+}
 
-	// Send LSP textDocument/implementation request
-	locations, err := s.sendImplementationsRequest(ctx, req)
+// Rename renames a symbol everywhere the language server finds it and
+// writes the edited files
+func (s *LSPService) Rename(ctx context.Context, req RenameRequest) (*RenameResult, error) {
+	if req.NewName == "" {
+		return nil, fmt.Errorf("rename requires a new name")
+	}
+	var edit workspaceEdit
+	err := s.request(ctx, req.File, req.Line, req.Column, req.Symbol, func(srv *LanguageServer, doc textDocumentPositionParams) error {
+		return srv.client.Call(ctx, "textDocument/rename", map[string]interface{}{
+			"textDocument": doc.TextDocument,
+			"position":     doc.Position,
+			"newName":      req.NewName,
+		}, &edit)
+	})
 	if err != nil {
 		return nil, err
 	}
-
-	return locations, nil
+	return s.applyEdit(edit)
 }
 
-// ensureServer ensures a language server is running for the given language
-func (s *LSPService) ensureServer(language string) error {
-	if _, exists := s.servers[language]; exists {
-		return nil // Already running
+// request opens file on its language's server, resolves the position of
+// the symbol and calls send with them
+func (s *LSPService) request(ctx context.Context, file string, line, column int, symbol string, send func(*LanguageServer, textDocumentPositionParams) error) error {
+	path, err := s.resolve(file)
+	if err != nil {
+		return err
 	}
+	language := detectLanguage(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Start language server based on language
-	server, err := startLanguageServer(language, s.projectPath)
+	srv, err := s.ensureServer(ctx, language)
+	if err != nil {
+		return fmt.Errorf("failed to start language server: %w", err)
+	}
+	content, err := srv.sync(path)
 	if err != nil {
 		return err
 	}
+	pos, err := documentPosition(content, line, column, symbol)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return send(srv, textDocumentPositionParams{
+		TextDocument: textDocumentIdentifier{URI: fileURI(path)},
+		Position:     pos,
+	})
+}
 
-	s.servers[language] = server
-	return nil
+// resolve returns the absolute path of a project file, refusing paths
+// outside the project
+func (s *LSPService) resolve(file string) (string, error) {
+	if file == "" {
+		return "", fmt.Errorf("file is required")
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(s.projectPath, path)
+	}
+	path = filepath.Clean(path)
+	if !s.contains(path) {
+		return "", fmt.Errorf("%s is outside the project", file)
+	}
+	return path, nil
 }
 
-// sendReferencesRequest sends an LSP textDocument/references request
-func (s *LSPService) sendReferencesRequest(ctx context.Context, req FindReferencesRequest) ([]Location, error) {
-	// Placeholder: Full LSP JSON-RPC implementation would go here
-	// For now, return a structured response that can be expanded
+func (s *LSPService) contains(path string) bool {
+	rel, err := filepath.Rel(s.projectPath, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
 
-	// In a full implementation, this would:
-	// 1. Convert file path to URI
-	// 2. Send LSP request: {"method": "textDocument/references", "params": {...}}
-	// 3. Parse response with locations
-	// 4. Convert URIs back to file paths
+// relative returns path relative to the project, or as is when it is
+// outside the project, such as a definition in the standard library
+func (s *LSPService) relative(path string) string {
+	if !s.contains(path) {
+		return path
+	}
+	rel, _ := filepath.Rel(s.projectPath, path)
+	return filepath.ToSlash(rel)
+}
 
-	return nil, fmt.Errorf("LSP integration not yet implemented - use fallback search")
+// documentPosition returns the LSP position of a 1-indexed line and
+// column, or of symbol on line (any line when 0)
+func documentPosition(content string, line, column int, symbol string) (position, error) {
+	if line > 0 && column > 0 {
+		return position{Line: line - 1, Character: utf16Offset(lineText(content, line-1), column-1)}, nil
+	}
+	if symbol == "" {
+		return position{}, fmt.Errorf("either symbol or line and column is required")
+	}
+	l, c, ok := symbolPosition(content, line, symbol)
+	if !ok {
+		if line > 0 {
+			return position{}, fmt.Errorf("symbol %q not found on line %d", symbol, line)
+		}
+		return position{}, fmt.Errorf("symbol %q not found", symbol)
+	}
+	return position{Line: l - 1, Character: utf16Offset(lineText(content, l-1), c-1)}, nil
 }
 
-// sendDefinitionRequest sends an LSP textDocument/definition request
-func (s *LSPService) sendDefinitionRequest(ctx context.Context, req GoToDefinitionRequest) (*Location, error) {
-	// Placeholder for full LSP implementation
-	return nil, fmt.Errorf("LSP integration not yet implemented - use fallback search")
+// locations converts a Location, Location[] or LocationLink[] result to
+// project locations with the text of their lines
+func (s *LSPService) locations(raw json.RawMessage) ([]Location, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" {
+		return nil, nil
+	}
+	type link struct {
+		lspLocation
+		TargetURI            string   `json:"targetUri"`
+		TargetSelectionRange lspRange `json:"targetSelectionRange"`
+	}
+	var links []link
+	if strings.HasPrefix(trimmed, "[") {
+		if err := json.Unmarshal(raw, &links); err != nil {
+			return nil, fmt.Errorf("invalid locations from language server: %w", err)
+		}
+	} else {
+		var one link
+		if err := json.Unmarshal(raw, &one); err != nil {
+			return nil, fmt.Errorf("invalid location from language server: %w", err)
+		}
+		links = []link{one}
+	}
+
+	contents := map[string]string{}
+	seen := map[Location]bool{}
+	var locations []Location
+	for _, l := range links {
+		uri, r := l.URI, l.Range
+		if l.TargetURI != "" {
+			uri, r = l.TargetURI, l.TargetSelectionRange
+		}
+		path, err := uriPath(uri)
+		if err != nil {
+			continue
+		}
+		content, ok := contents[path]
+		if !ok {
+			if data, err := os.ReadFile(path); err == nil {
+				content = string(data)
+			}
+			contents[path] = content
+		}
+		text := lineText(content, r.Start.Line)
+		loc := Location{
+			File:   s.relative(path),
+			Line:   r.Start.Line + 1,
+			Column: runeColumn(text, r.Start.Character) + 1,
+			Text:   strings.TrimSpace(text),
+		}
+		if !seen[loc] {
+			seen[loc] = true
+			locations = append(locations, loc)
+		}
+	}
+	return locations, nil
 }
 
-// sendImplementationsRequest sends an LSP textDocument/implementation request
-func (s *LSPService) sendImplementationsRequest(ctx context.Context, req FindImplementationsRequest) ([]Location, error) {
-	// Placeholder for full LSP implementation
-	return nil, fmt.Errorf("LSP integration not yet implemented - use fallback search")
+// applyEdit writes the text edits of a rename. Every file is checked and
+// edited in memory before any is written, so a rename the service cannot
+// apply leaves the project as it was.
+func (s *LSPService) applyEdit(edit workspaceEdit) (*RenameResult, error) {
+	byPath := map[string][]textEdit{}
+	add := func(uri string, edits []textEdit) error {
+		path, err := uriPath(uri)
+		if err != nil {
+			return err
+		}
+		if !s.contains(path) {
+			return fmt.Errorf("rename would edit %s, outside the project", path)
+		}
+		byPath[path] = append(byPath[path], edits...)
+		return nil
+	}
+	for uri, edits := range edit.Changes {
+		if err := add(uri, edits); err != nil {
+			return nil, err
+		}
+	}
+	for _, dc := range edit.DocumentChanges {
+		if dc.Kind != "" {
+			return nil, fmt.Errorf("rename needs a %s file operation, which is not supported", dc.Kind)
+		}
+		if err := add(dc.TextDocument.URI, dc.Edits); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RenameResult{}
+	updated := map[string]string{}
+	for path, edits := range byPath {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content, err := applyTextEdits(string(data), edits)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.relative(path), err)
+		}
+		updated[path] = content
+		result.Edits += len(edits)
+	}
+	for path, content := range updated {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(content), info.Mode().Perm()); err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, s.relative(path))
+	}
+	sort.Strings(result.Files)
+	return result, nil
 }
 
-// startLanguageServer starts a language server process
-func startLanguageServer(language, projectPath string) (*LanguageServer, error) {
-	var command string
-	var args []string
+// applyTextEdits applies non-overlapping edits to content
+func applyTextEdits(content string, edits []textEdit) (string, error) {
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := make([]span, 0, len(edits))
+	for _, e := range edits {
+		start, err := byteOffset(content, e.Range.Start)
+		if err != nil {
+			return "", err
+		}
+		end, err := byteOffset(content, e.Range.End)
+		if err != nil {
+			return "", err
+		}
+		spans = append(spans, span{start, end, e.NewText})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	for i, sp := range spans {
+		if sp.end < sp.start || (i > 0 && sp.end > spans[i-1].start) {
+			return "", fmt.Errorf("overlapping edits")
+		}
+		content = content[:sp.start] + sp.text + content[sp.end:]
+	}
+	return content, nil
+}
 
-	switch language {
-	case "go":
-		command = "gopls"
-		args = []string{"serve"}
-	case "typescript", "javascript":
-		command = "typescript-language-server"
-		args = []string{"--stdio"}
-	case "python":
-		command = "pylsp"
-		args = []string{}
-	default:
+// ensureServer ensures a language server is running for the given language
+func (s *LSPService) ensureServer(ctx context.Context, language string) (*LanguageServer, error) {
+	if server, exists := s.servers[language]; exists {
+		select {
+		case <-server.client.Done():
+			delete(s.servers, language) // Exited; start it again
+		default:
+			return server, nil
+		}
+	}
+
+	command := s.commands[language]
+	if len(command) == 0 {
 		return nil, fmt.Errorf("unsupported language: %s", language)
 	}
+	server, err := s.startLanguageServer(ctx, language, command)
+	if err != nil {
+		return nil, err
+	}
+
+	s.servers[language] = server
+	return server, nil
+}
 
+// startLanguageServer starts a language server process and initializes it
+// for the project
+func (s *LSPService) startLanguageServer(ctx context.Context, language string, command []string) (*LanguageServer, error) {
+	conn, pid, err := s.connect(command, s.projectPath)
+	if err != nil {
+		return nil, err
+	}
 	server := &LanguageServer{
-		Language: language,
-		Command:  command,
-		Args:     args,
+		Language:  language,
+		Command:   command[0],
+		Args:      command[1:],
+		PID:       pid,
+		StartedAt: time.Now().UTC(),
+		client:    NewClient(conn),
+		docs:      make(map[string]*openDocument),
 	}
 
-	// In a full implementation, start the process here
-	// For now, just return the server definition
-
+	ctx, cancel := context.WithTimeout(ctx, initializeTimeout)
+	defer cancel()
+	root := fileURI(s.projectPath)
+	err = server.client.Call(ctx, "initialize", map[string]interface{}{
+		"processId": os.Getpid(),
+		"rootUri":   root,
+		"workspaceFolders": []map[string]string{
+			{"uri": root, "name": filepath.Base(s.projectPath)},
+		},
+		"capabilities": map[string]interface{}{
+			"workspace": map[string]interface{}{
+				"workspaceEdit":    map[string]bool{"documentChanges": true},
+				"configuration":    true,
+				"workspaceFolders": true,
+			},
+			"textDocument": map[string]interface{}{
+				"synchronization": map[string]bool{"dynamicRegistration": false},
+				"definition":      map[string]bool{"linkSupport": true},
+				"implementation":  map[string]bool{"linkSupport": true},
+				"references":      map[string]bool{},
+				"rename":          map[string]bool{},
+			},
+		},
+	}, nil)
+	if err == nil {
+		err = server.client.Notify("initialized", map[string]interface{}{})
+	}
+	if err != nil {
+		server.client.Close()
+		return nil, fmt.Errorf("%s did not initialize: %w", command[0], err)
+	}
 	return server, nil
 }
 
+// sync opens path on the server, or sends its new content if it changed
+// on disk since, and returns the content
+func (srv *LanguageServer) sync(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	content := string(data)
+	uri := fileURI(path)
+
+	doc, open := srv.docs[path]
+	switch {
+	case !open:
+		err = srv.client.Notify("textDocument/didOpen", map[string]interface{}{
+			"textDocument": map[string]interface{}{
+				"uri":        uri,
+				"languageId": srv.Language,
+				"version":    1,
+				"text":       content,
+			},
+		})
+		srv.docs[path] = &openDocument{version: 1, content: content}
+	case doc.content != content:
+		doc.version++
+		doc.content = content
+		err = srv.client.Notify("textDocument/didChange", map[string]interface{}{
+			"textDocument":   map[string]interface{}{"uri": uri, "version": doc.version},
+			"contentChanges": []map[string]string{{"text": content}},
+		})
+	}
+	return content, err
+}
+
+// shutdown asks the server to exit and closes the connection
+func (srv *LanguageServer) shutdown() error {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.client.Call(ctx, "shutdown", nil, nil); err == nil {
+		_ = srv.client.Notify("exit", nil)
+	}
+	return srv.client.Close()
+}
+
+// Servers describes the running language servers
+func (s *LSPService) Servers() []ServerStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]ServerStatus, 0, len(s.servers))
+	for _, server := range s.servers {
+		statuses = append(statuses, ServerStatus{
+			Language:  server.Language,
+			Command:   strings.TrimSpace(server.Command + " " + strings.Join(server.Args, " ")),
+			PID:       server.PID,
+			StartedAt: server.StartedAt,
+			OpenFiles: len(server.docs),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Language < statuses[j].Language })
+	return statuses
+}
+
+// processConn is the stdio of a language server process; closing it stops
+// the process
+type processConn struct {
+	io.ReadCloser
+	stdin io.WriteCloser
+	cmd   *exec.Cmd
+}
+
+func (p *processConn) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+func (p *processConn) Close() error {
+	_ = p.stdin.Close()
+	exited := make(chan struct{})
+	go func() {
+		_ = p.cmd.Wait()
+		close(exited)
+	}()
+	select {
+	case <-exited:
+	case <-time.After(shutdownTimeout):
+		_ = p.cmd.Process.Kill()
+		<-exited
+	}
+	return nil
+}
+
+// startProcess starts a language server in root
+func startProcess(command []string, root string) (io.ReadWriteCloser, int, error) {
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, 0, err
+	}
+	if err := cmd.Start(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, 0, fmt.Errorf("%s is not installed", command[0])
+		}
+		return nil, 0, err
+	}
+	return &processConn{ReadCloser: stdout, stdin: stdin, cmd: cmd}, cmd.Process.Pid, nil
+}
+
 // detectLanguage detects programming language from file extension
 func detectLanguage(filePath string) string {
 	// Simple extension-based detection
@@ -225,12 +644,31 @@ type FindImplementationsRequest struct {
 	Symbol string // Optional: symbol name if known
 }
 
+// RenameRequest defines parameters for renaming a symbol
+type RenameRequest struct {
+	File    string // File path
+	Line    int    // Line number (1-indexed)
+	Column  int    // Column number (1-indexed)
+	Symbol  string // Symbol name, located on Line when Column is 0
+	NewName string
+}
+
+// RenameResult is what a rename changed
+type RenameResult struct {
+	Files []string `json:"files"` // Edited files, relative to the project
+	Edits int      `json:"edits"`
+}
+
 // Close closes all language servers
 func (s *LSPService) Close() error {
-	// In full implementation, stop all server processes
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
 	for _, server := range s.servers {
-		_ = server // Would stop the process here
+		if err := server.shutdown(); err != nil && !errors.Is(err, errClientClosed) {
+			errs = append(errs, err)
+		}
 	}
 	s.servers = make(map[string]*LanguageServer)
-	return nil
+	return errors.Join(errs...)
 }
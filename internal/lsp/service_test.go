@@ -0,0 +1,258 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const mainGo = `package main
+
+func greet() string { return "hi" }
+
+func main() {
+	println(greet())
+}
+`
+
+// fakeServer answers requests with handle and records the notifications
+// it receives
+type fakeServer struct {
+	handle func(method string, params json.RawMessage) interface{}
+
+	mu            sync.Mutex
+	notifications []string
+}
+
+func (f *fakeServer) connect(command []string, root string) (io.ReadWriteCloser, int, error) {
+	client, server := net.Pipe()
+	go func() {
+		r := bufio.NewReader(server)
+		for {
+			msg, err := readMessage(r)
+			if err != nil {
+				return
+			}
+			if msg.ID == nil {
+				f.mu.Lock()
+				f.notifications = append(f.notifications, msg.Method)
+				f.mu.Unlock()
+				if msg.Method == "exit" {
+					server.Close()
+					return
+				}
+				continue
+			}
+			var result interface{}
+			if f.handle != nil {
+				result = f.handle(msg.Method, msg.Params)
+			}
+			data, _ := json.Marshal(result)
+			_ = writeMessage(server, &message{JSONRPC: "2.0", ID: msg.ID, Result: data})
+		}
+	}()
+	return client, 0, nil
+}
+
+func (f *fakeServer) received(method string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, m := range f.notifications {
+		if m == method {
+			n++
+		}
+	}
+	return n
+}
+
+func newTestService(t *testing.T, handle func(method string, params json.RawMessage) interface{}) (*LSPService, *fakeServer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "main.go"), []byte(mainGo), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewLSPService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake := &fakeServer{handle: handle}
+	s.connect = fake.connect
+	t.Cleanup(func() { s.Close() })
+	return s, fake, dir
+}
+
+func TestServiceNavigation(t *testing.T) {
+	var mu sync.Mutex
+	var positions []position
+	var dir string
+	s, fake, root := newTestService(t, func(method string, params json.RawMessage) interface{} {
+		var p textDocumentPositionParams
+		_ = json.Unmarshal(params, &p)
+		mu.Lock()
+		positions = append(positions, p.Position)
+		mu.Unlock()
+		uri := fileURI(filepath.Join(dir, "main.go"))
+		switch method {
+		case "textDocument/definition":
+			return []map[string]interface{}{{
+				"targetUri":            uri,
+				"targetRange":          lspRange{Start: position{2, 0}, End: position{2, 35}},
+				"targetSelectionRange": lspRange{Start: position{2, 5}, End: position{2, 10}},
+			}}
+		case "textDocument/references":
+			return []lspLocation{
+				{URI: uri, Range: lspRange{Start: position{2, 5}, End: position{2, 10}}},
+				{URI: uri, Range: lspRange{Start: position{5, 9}, End: position{5, 14}}},
+			}
+		case "textDocument/implementation":
+			return nil
+		}
+		return map[string]interface{}{}
+	})
+	dir = root
+
+	ctx := context.Background()
+	def, err := s.GoToDefinition(ctx, GoToDefinitionRequest{File: "main.go", Line: 6, Symbol: "greet"})
+	if err != nil {
+		t.Fatalf("GoToDefinition failed: %v", err)
+	}
+	if *def != (Location{File: "main.go", Line: 3, Column: 6, Text: `func greet() string { return "hi" }`}) {
+		t.Errorf("definition = %+v", def)
+	}
+
+	refs, err := s.FindReferences(ctx, FindReferencesRequest{File: filepath.Join(dir, "main.go"), Line: 3, Column: 6})
+	if err != nil {
+		t.Fatalf("FindReferences failed: %v", err)
+	}
+	if len(refs) != 2 || refs[1].Line != 6 || refs[1].Column != 10 || refs[1].Text != "println(greet())" {
+		t.Errorf("references = %+v", refs)
+	}
+
+	impls, err := s.FindImplementations(ctx, FindImplementationsRequest{File: "main.go", Symbol: "main"})
+	if err != nil || len(impls) != 0 {
+		t.Errorf("implementations = %+v, %v", impls, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	// initialize, then the symbol on line 6, line 3 column 6 and the first "main" as a word
+	want := []position{{0, 0}, {5, 9}, {2, 5}, {0, 8}}
+	if len(positions) != len(want) {
+		t.Fatalf("positions = %+v", positions)
+	}
+	for i := range want {
+		if positions[i] != want[i] {
+			t.Errorf("request %d at %+v, want %+v", i, positions[i], want[i])
+		}
+	}
+	if n := fake.received("textDocument/didOpen"); n != 1 {
+		t.Errorf("opened main.go %d times, want once", n)
+	}
+	if servers := s.Servers(); len(servers) != 1 || servers[0].Language != "go" || servers[0].Command != "gopls serve" || servers[0].OpenFiles != 1 {
+		t.Errorf("servers = %+v", servers)
+	}
+}
+
+func TestServiceRename(t *testing.T) {
+	var dir string
+	outside := false
+	s, fake, root := newTestService(t, func(method string, params json.RawMessage) interface{} {
+		if method != "textDocument/rename" {
+			return nil
+		}
+		uri := fileURI(filepath.Join(dir, "main.go"))
+		if outside {
+			uri = fileURI(filepath.Join(filepath.Dir(dir), "other.go"))
+		}
+		return workspaceEdit{DocumentChanges: []textDocumentEdit{{
+			TextDocument: textDocumentIdentifier{URI: uri},
+			Edits: []textEdit{
+				{Range: lspRange{Start: position{2, 5}, End: position{2, 10}}, NewText: "greeting"},
+				{Range: lspRange{Start: position{5, 9}, End: position{5, 14}}, NewText: "greeting"},
+			},
+		}}}
+	})
+	dir = root
+
+	ctx := context.Background()
+	res, err := s.Rename(ctx, RenameRequest{File: "main.go", Symbol: "greet", NewName: "greeting"})
+	if err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if len(res.Files) != 1 || res.Files[0] != "main.go" || res.Edits != 2 {
+		t.Errorf("rename result = %+v", res)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "main.go"))
+	if want := strings.ReplaceAll(mainGo, "greet()", "greeting()"); string(data) != want {
+		t.Errorf("main.go after rename:\n%s", data)
+	}
+
+	// The next request sends the server the renamed file
+	outside = true
+	if _, err := s.Rename(ctx, RenameRequest{File: "main.go", Symbol: "greeting", NewName: "hello"}); err == nil || !strings.Contains(err.Error(), "outside the project") {
+		t.Errorf("a rename outside the project = %v", err)
+	}
+	if n := fake.received("textDocument/didChange"); n != 1 {
+		t.Errorf("sent %d changes of main.go, want 1", n)
+	}
+	if after, _ := os.ReadFile(filepath.Join(dir, "main.go")); string(after) != string(data) {
+		t.Error("a refused rename must leave the files as they were")
+	}
+
+	if _, err := s.Rename(ctx, RenameRequest{File: "../main.go", Symbol: "x", NewName: "y"}); err == nil {
+		t.Error("expected an error for a file outside the project")
+	}
+	if _, err := s.Rename(ctx, RenameRequest{File: "main.go", Symbol: "missing", NewName: "y"}); err == nil || !strings.Contains(err.Error(), `symbol "missing" not found`) {
+		t.Errorf("renaming a missing symbol = %v", err)
+	}
+}
+
+func TestServiceUnsupportedLanguage(t *testing.T) {
+	s, _, dir := newTestService(t, nil)
+	if err := os.WriteFile(filepath.Join(dir, "main.rs"), []byte("fn main() {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GoToDefinition(context.Background(), GoToDefinitionRequest{File: "main.rs", Symbol: "main"}); err == nil || !strings.Contains(err.Error(), "unsupported language") {
+		t.Errorf("definition in a Rust file = %v", err)
+	}
+}
+
+func TestPool(t *testing.T) {
+	p := NewPool(time.Minute, map[string][]string{"python": {"pyright-langserver", "--stdio"}})
+	root := t.TempDir()
+	a, err := p.Service("proj-1", root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := p.Service("proj-1", root); b != a {
+		t.Error("expected the project's service to be reused")
+	}
+	if a.commands["python"][0] != "pyright-langserver" || a.commands["go"][0] != "gopls" {
+		t.Errorf("commands = %v", a.commands)
+	}
+	if moved, _ := p.Service("proj-1", t.TempDir()); moved == a {
+		t.Error("expected a new service when the project moved")
+	}
+
+	if _, err := p.Service("proj-2", t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if n := p.Reap(time.Now()); n != 0 {
+		t.Errorf("reaped %d projects in use", n)
+	}
+	if n := p.Reap(time.Now().Add(2 * time.Minute)); n != 2 {
+		t.Errorf("reaped %d idle projects, want 2", n)
+	}
+	if servers := p.Servers("proj-1"); len(servers) != 0 {
+		t.Errorf("servers after reaping = %+v", servers)
+	}
+}
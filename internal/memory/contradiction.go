@@ -0,0 +1,270 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// ContradictionStore is the subset of database.Database the contradiction
+// detector needs.
+type ContradictionStore interface {
+	ListLessonsWithEmbeddings(projectID, orgID string, limit int) ([]*models.Lesson, error)
+	SupersedeLesson(id, byID string, at time.Time) error
+}
+
+// Verdict is an adjudicator's ruling on two similar lessons
+type Verdict struct {
+	Contradicts bool   `json:"contradicts"`
+	KeepOlder   bool   `json:"keep_older,omitempty"` // The older lesson is the better guidance
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Adjudicator decides whether a newer lesson contradicts an older one
+type Adjudicator interface {
+	Adjudicate(ctx context.Context, older, newer *models.Lesson) (Verdict, error)
+}
+
+// ContradictionConfig configures the contradiction detector
+type ContradictionConfig struct {
+	Similarity    float64 // Cosine similarity at or above which two lessons are adjudicated
+	MaxCandidates int     // Most similar lessons adjudicated per new lesson
+}
+
+// DefaultContradictionConfig returns the defaults: lessons at least 0.8
+// similar are adjudicated, the 3 most similar per new lesson
+func DefaultContradictionConfig() ContradictionConfig {
+	return ContradictionConfig{Similarity: 0.8, MaxCandidates: 3}
+}
+
+// Supersession records a lesson superseded by one contradicting it
+type Supersession struct {
+	LessonID     string  `json:"lesson_id"`
+	SupersededBy string  `json:"superseded_by"`
+	Similarity   float32 `json:"similarity"`
+	Reason       string  `json:"reason,omitempty"`
+}
+
+// contradictionScanLimit is how many of a project's most recent lessons a
+// new lesson is compared with
+const contradictionScanLimit = 200
+
+// ContradictionDetector keeps contradicting lessons out of prompts. A new
+// lesson is compared with the project's current lessons embedded by the
+// same embedder; the most similar are put to the adjudicator, and whichever
+// of a contradicting pair it does not keep, by default the older, is
+// marked superseded.
+type ContradictionDetector struct {
+	store       ContradictionStore
+	adjudicator Adjudicator
+	cfg         ContradictionConfig
+}
+
+// NewContradictionDetector returns a detector putting similar lessons to
+// adjudicator, or to a PolarityAdjudicator when it is nil. It returns nil
+// without a store.
+func NewContradictionDetector(store ContradictionStore, adjudicator Adjudicator, cfg ContradictionConfig) *ContradictionDetector {
+	if store == nil {
+		return nil
+	}
+	def := DefaultContradictionConfig()
+	if cfg.Similarity <= 0 || cfg.Similarity > 1 {
+		cfg.Similarity = def.Similarity
+	}
+	if cfg.MaxCandidates <= 0 {
+		cfg.MaxCandidates = def.MaxCandidates
+	}
+	if adjudicator == nil {
+		adjudicator = PolarityAdjudicator{}
+	}
+	return &ContradictionDetector{store: store, adjudicator: adjudicator, cfg: cfg}
+}
+
+// Check adjudicates a newly stored lesson against the similar lessons
+// already recorded for its project, or for a shared lesson those in the
+// same status of its organization's shared tier, and supersedes the
+// losers. The lesson
+// must carry its Embedding. When the adjudicator fails, the polarity
+// heuristic rules instead. Once the new lesson itself is superseded there
+// is nothing more to check.
+func (d *ContradictionDetector) Check(ctx context.Context, lesson *models.Lesson) ([]Supersession, error) {
+	if d == nil || lesson == nil || len(lesson.Embedding) == 0 {
+		return nil, nil
+	}
+	existing, err := d.store.ListLessonsWithEmbeddings(lesson.ProjectID, lesson.OrgID, contradictionScanLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	type candidate struct {
+		lesson     *models.Lesson
+		similarity float32
+	}
+	var candidates []candidate
+	for _, l := range existing {
+		// Lessons recorded since are checked against this one in turn, and
+		// checks run concurrently
+		if l.ID == lesson.ID || l.EmbeddingVersion != lesson.EmbeddingVersion || l.CreatedAt.After(lesson.CreatedAt) || l.ShareStatus != lesson.ShareStatus {
+			continue
+		}
+		if sim := CosineSimilarity(lesson.Embedding, l.Embedding); float64(sim) >= d.cfg.Similarity {
+			candidates = append(candidates, candidate{lesson: l, similarity: sim})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].similarity > candidates[j].similarity
+	})
+	if len(candidates) > d.cfg.MaxCandidates {
+		candidates = candidates[:d.cfg.MaxCandidates]
+	}
+
+	var superseded []Supersession
+	for _, c := range candidates {
+		verdict, err := d.adjudicator.Adjudicate(ctx, c.lesson, lesson)
+		if err != nil {
+			log.Printf("[Memory] Lesson adjudication failed, using the polarity heuristic: %v", err)
+			verdict, _ = PolarityAdjudicator{}.Adjudicate(ctx, c.lesson, lesson)
+		}
+		if !verdict.Contradicts {
+			continue
+		}
+		s := Supersession{LessonID: c.lesson.ID, SupersededBy: lesson.ID, Similarity: c.similarity, Reason: verdict.Reason}
+		if verdict.KeepOlder {
+			s.LessonID, s.SupersededBy = lesson.ID, c.lesson.ID
+		}
+		if err := d.store.SupersedeLesson(s.LessonID, s.SupersededBy, time.Now()); err != nil {
+			return superseded, err
+		}
+		log.Printf("[Memory] Lesson %s superseded by contradicting lesson %s: %s", s.LessonID, s.SupersededBy, s.Reason)
+		superseded = append(superseded, s)
+		if verdict.KeepOlder {
+			break
+		}
+	}
+	return superseded, nil
+}
+
+// PolarityAdjudicator is the fallback adjudicator. Two similar lessons
+// contradict when one tells agents not to do something and the other tells
+// them to, and the newer one is always kept. It cannot tell a reversal from
+// a restatement in opposite terms ("never skip X", "always run X"), but
+// superseding a restatement loses nothing.
+type PolarityAdjudicator struct{}
+
+// negativeMarkers and positiveMarkers are checked in that order, since
+// "must not" contains "must"
+var (
+	negativeMarkers = []string{"never", "don't", "do not", "avoid", "must not", "mustn't", "should not", "shouldn't", "stop using", "no longer"}
+	positiveMarkers = []string{"always", "must", "should", "prefer", "make sure", "ensure", "use"}
+)
+
+// Adjudicate compares the lessons' polarity
+func (PolarityAdjudicator) Adjudicate(_ context.Context, older, newer *models.Lesson) (Verdict, error) {
+	po, pn := lessonPolarity(older), lessonPolarity(newer)
+	if po == 0 || pn == 0 || po == pn {
+		return Verdict{}, nil
+	}
+	return Verdict{Contradicts: true, Reason: "opposite guidance on the same subject; the newer lesson is kept"}, nil
+}
+
+// lessonPolarity returns -1 for a lesson telling agents not to do
+// something, 1 for one telling them to, and 0 when it does neither
+func lessonPolarity(l *models.Lesson) int {
+	text := " " + strings.ToLower(l.Title+" "+l.Detail) + " "
+	for _, m := range negativeMarkers {
+		if containsWord(text, m) {
+			return -1
+		}
+	}
+	for _, m := range positiveMarkers {
+		if containsWord(text, m) {
+			return 1
+		}
+	}
+	return 0
+}
+
+// containsWord reports whether phrase appears in text between non-letters
+func containsWord(text, phrase string) bool {
+	for i := 0; ; {
+		j := strings.Index(text[i:], phrase)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(phrase)
+		if !isLetter(text[start-1]) && (end >= len(text) || !isLetter(text[end])) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func isLetter(b byte) bool {
+	return b >= 'a' && b <= 'z'
+}
+
+// CompleteFunc sends a system prompt and a prompt to a model and returns
+// its reply
+type CompleteFunc func(ctx context.Context, systemPrompt, prompt string) (string, error)
+
+// LLMAdjudicator asks a model whether a newer lesson contradicts an older
+// one, and which is the better guidance
+type LLMAdjudicator struct {
+	complete CompleteFunc
+}
+
+// NewLLMAdjudicator returns an adjudicator asking the model complete
+// reaches. It returns nil without one.
+func NewLLMAdjudicator(complete CompleteFunc) *LLMAdjudicator {
+	if complete == nil {
+		return nil
+	}
+	return &LLMAdjudicator{complete: complete}
+}
+
+const adjudicatorSystemPrompt = `You review lessons an AI coding agent learned while working on a project. Lessons are injected into the agent's prompts, so two lessons giving opposite guidance confuse it.
+
+Decide whether the NEWER lesson contradicts the OLDER one: following one would mean going against the other. Lessons about different things, or that agree, or where one refines the other, do not contradict.
+
+When they contradict, keep the newer lesson unless the older one is clearly better supported by its detail.
+
+Reply with JSON only: {"contradicts": true|false, "keep": "newer"|"older", "reason": "one sentence"}`
+
+// Adjudicate asks the model for a verdict
+func (a *LLMAdjudicator) Adjudicate(ctx context.Context, older, newer *models.Lesson) (Verdict, error) {
+	prompt := fmt.Sprintf("OLDER lesson (%s, %s):\n%s\n%s\n\nNEWER lesson (%s, %s):\n%s\n%s",
+		older.Category, older.CreatedAt.Format(time.RFC3339), older.Title, older.Detail,
+		newer.Category, newer.CreatedAt.Format(time.RFC3339), newer.Title, newer.Detail)
+	reply, err := a.complete(ctx, adjudicatorSystemPrompt, prompt)
+	if err != nil {
+		return Verdict{}, err
+	}
+	return parseVerdict(reply)
+}
+
+// parseVerdict reads the JSON object in a model's reply
+func parseVerdict(reply string) (Verdict, error) {
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	if start < 0 || end < start {
+		return Verdict{}, fmt.Errorf("no verdict in reply: %s", truncateStr(reply, 200))
+	}
+	var raw struct {
+		Contradicts bool   `json:"contradicts"`
+		Keep        string `json:"keep"`
+		Reason      string `json:"reason"`
+	}
+	if err := json.Unmarshal([]byte(reply[start:end+1]), &raw); err != nil {
+		return Verdict{}, fmt.Errorf("invalid verdict: %w", err)
+	}
+	return Verdict{
+		Contradicts: raw.Contradicts,
+		KeepOlder:   raw.Contradicts && strings.EqualFold(strings.TrimSpace(raw.Keep), "older"),
+		Reason:      raw.Reason,
+	}, nil
+}
@@ -0,0 +1,131 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// contradictionStore is a ContradictionStore kept in memory
+type contradictionStore struct {
+	lessons []*models.Lesson
+}
+
+func (s *contradictionStore) ListLessonsWithEmbeddings(projectID, orgID string, limit int) ([]*models.Lesson, error) {
+	var list []*models.Lesson
+	for _, l := range s.lessons {
+		if l.ProjectID == projectID && l.OrgID == orgID && l.SupersededBy == "" && len(list) < limit {
+			list = append(list, l)
+		}
+	}
+	return list, nil
+}
+
+func (s *contradictionStore) SupersedeLesson(id, byID string, at time.Time) error {
+	for _, l := range s.lessons {
+		if l.ID == id {
+			l.SupersededBy, l.SupersededAt = byID, &at
+		}
+	}
+	return nil
+}
+
+// embeddedLesson returns a lesson embedded by the hash embedder
+func embeddedLesson(t *testing.T, id, title, detail string) *models.Lesson {
+	t.Helper()
+	vecs, version, err := EmbedWithVersion(context.Background(), NewHashEmbedder(), []string{title + " " + detail})
+	if err != nil {
+		t.Fatalf("embedding failed: %v", err)
+	}
+	return &models.Lesson{ID: id, ProjectID: "p1", Title: title, Detail: detail, Embedding: vecs[0], EmbeddingVersion: version, CreatedAt: time.Now()}
+}
+
+func TestContradictionDetector_SupersedesOlderLesson(t *testing.T) {
+	older := embeddedLesson(t, "l1", "Vendor dependencies", "Always run go mod vendor after adding a dependency")
+	unrelated := embeddedLesson(t, "l2", "Close response bodies", "Defer resp.Body.Close() after every HTTP request")
+	newer := embeddedLesson(t, "l3", "Vendor dependencies", "Never run go mod vendor after adding a dependency")
+	store := &contradictionStore{lessons: []*models.Lesson{older, unrelated, newer}}
+
+	d := NewContradictionDetector(store, nil, ContradictionConfig{Similarity: 0.5})
+	got, err := d.Check(context.Background(), newer)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if len(got) != 1 || got[0].LessonID != "l1" || got[0].SupersededBy != "l3" {
+		t.Fatalf("expected l1 superseded by l3, got %+v", got)
+	}
+	if older.SupersededBy != "l3" || unrelated.SupersededBy != "" || newer.SupersededBy != "" {
+		t.Errorf("unexpected marks: l1=%q l2=%q l3=%q", older.SupersededBy, unrelated.SupersededBy, newer.SupersededBy)
+	}
+}
+
+// fixedAdjudicator returns the same verdict, or error, for every pair
+type fixedAdjudicator struct {
+	verdict Verdict
+	err     error
+	calls   int
+}
+
+func (a *fixedAdjudicator) Adjudicate(context.Context, *models.Lesson, *models.Lesson) (Verdict, error) {
+	a.calls++
+	return a.verdict, a.err
+}
+
+func TestContradictionDetector_AdjudicatorDecides(t *testing.T) {
+	older := embeddedLesson(t, "l1", "Retry flaky tests", "Always retry the test suite once before reporting a failure")
+	newer := embeddedLesson(t, "l2", "Retry flaky tests", "Do not retry the test suite before reporting a failure")
+
+	// The adjudicator keeps the older lesson
+	store := &contradictionStore{lessons: []*models.Lesson{older, newer}}
+	adj := &fixedAdjudicator{verdict: Verdict{Contradicts: true, KeepOlder: true, Reason: "older is validated"}}
+	got, _ := NewContradictionDetector(store, adj, ContradictionConfig{Similarity: 0.5}).Check(context.Background(), newer)
+	if len(got) != 1 || newer.SupersededBy != "l1" || older.SupersededBy != "" {
+		t.Errorf("expected the newer lesson superseded, got %+v", got)
+	}
+
+	// Lessons the adjudicator finds compatible are both kept
+	older.SupersededBy, newer.SupersededBy = "", ""
+	adj = &fixedAdjudicator{verdict: Verdict{}}
+	if got, _ := NewContradictionDetector(store, adj, ContradictionConfig{Similarity: 0.5}).Check(context.Background(), newer); len(got) != 0 || adj.calls != 1 {
+		t.Errorf("expected one adjudication and nothing superseded, got %+v", got)
+	}
+
+	// A failing adjudicator falls back to the polarity heuristic
+	adj = &fixedAdjudicator{err: errors.New("provider down")}
+	got, _ = NewContradictionDetector(store, adj, ContradictionConfig{Similarity: 0.5}).Check(context.Background(), newer)
+	if len(got) != 1 || older.SupersededBy != "l2" {
+		t.Errorf("expected the heuristic to supersede the older lesson, got %+v", got)
+	}
+}
+
+func TestLessonPolarity(t *testing.T) {
+	for text, want := range map[string]int{
+		"Always vendor dependencies":      1,
+		"Use the retry helper":            1,
+		"Must not edit generated files":   -1,
+		"Don't use the retry helper":      -1,
+		"Avoid global state":              -1,
+		"Build failed in parser.go":       0,
+		"The user's refusal was recorded": 0, // "use" inside a word
+	} {
+		if got := lessonPolarity(&models.Lesson{Title: text}); got != want {
+			t.Errorf("lessonPolarity(%q) = %d, want %d", text, got, want)
+		}
+	}
+}
+
+func TestParseVerdict(t *testing.T) {
+	v, err := parseVerdict("```json\n{\"contradicts\": true, \"keep\": \"Older\", \"reason\": \"validated by CI\"}\n```")
+	if err != nil || !v.Contradicts || !v.KeepOlder || v.Reason != "validated by CI" {
+		t.Errorf("unexpected verdict %+v, %v", v, err)
+	}
+	if v, _ := parseVerdict(`{"contradicts": false, "keep": "older"}`); v.KeepOlder {
+		t.Error("expected keep ignored when the lessons do not contradict")
+	}
+	if _, err := parseVerdict("I am not sure"); err == nil {
+		t.Error("expected an error for a reply without JSON")
+	}
+}
@@ -0,0 +1,246 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// DefaultEmbeddingCacheSize is how many embeddings an EmbeddingCache keeps
+// in memory unless told otherwise
+const DefaultEmbeddingCacheSize = 4096
+
+// EmbeddingCacheStore keeps cached embeddings past a restart
+type EmbeddingCacheStore interface {
+	GetCachedEmbeddings(keys []string) (map[string][]float32, error)
+	StoreCachedEmbeddings(version string, entries map[string][]float32) error
+	DeleteCachedEmbeddings(before time.Time) (int64, error)
+}
+
+// EmbeddingCacheStats reports how often embeddings were served from the
+// cache instead of the embedding model
+type EmbeddingCacheStats struct {
+	MemoryHits int64   `json:"memory_hits"`
+	StoreHits  int64   `json:"store_hits"` // Found in the database after missing in memory
+	Misses     int64   `json:"misses"`     // Embedded by the model
+	HitRate    float64 `json:"hit_rate"`
+	Entries    int     `json:"entries"` // Embeddings held in memory
+	MaxEntries int     `json:"max_entries"`
+}
+
+// EmbeddingCacheKey is the key of text's embedding by the embedder of
+// version: the hex SHA-256 of both
+func EmbeddingCacheKey(version, text string) string {
+	sum := sha256.Sum256([]byte(version + "\x00" + text))
+	return hex.EncodeToString(sum[:])
+}
+
+// EmbeddingCache keeps the embeddings of texts already embedded, the most
+// recently used in memory and all of them in the store, so the same text
+// is embedded by a model only once
+type EmbeddingCache struct {
+	store      EmbeddingCacheStore
+	maxEntries int
+
+	mu      sync.Mutex
+	lru     *list.List // Of *embeddingCacheEntry, most recently used first
+	entries map[string]*list.Element
+
+	memoryHits, storeHits, misses int64
+}
+
+type embeddingCacheEntry struct {
+	key string
+	vec []float32
+}
+
+// NewEmbeddingCache creates a cache keeping up to maxEntries embeddings in
+// memory, DefaultEmbeddingCacheSize if it is not positive, backed by store,
+// which may be nil
+func NewEmbeddingCache(store EmbeddingCacheStore, maxEntries int) *EmbeddingCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultEmbeddingCacheSize
+	}
+	return &EmbeddingCache{
+		store:      store,
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// Wrap returns an embedder answering from the cache and embedding only the
+// texts it has not seen with e. Embedders that report no version, and the
+// hash embedder, which is cheaper to run than a lookup, are returned as
+// they are. A FallbackEmbedder's vectors may come from either of its
+// embedders, so wrap its primary rather than the fallback itself.
+func (c *EmbeddingCache) Wrap(e Embedder) Embedder {
+	version := EmbeddingVersion(e)
+	if c == nil || version == "" || version == HashEmbeddingVersion {
+		return e
+	}
+	if _, ok := e.(*FallbackEmbedder); ok {
+		return e
+	}
+	return &cachedEmbedder{cache: c, inner: e, version: version}
+}
+
+// Stats returns the cache's hit counts since it was created
+func (c *EmbeddingCache) Stats() EmbeddingCacheStats {
+	if c == nil {
+		return EmbeddingCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := EmbeddingCacheStats{
+		MemoryHits: c.memoryHits,
+		StoreHits:  c.storeHits,
+		Misses:     c.misses,
+		Entries:    c.lru.Len(),
+		MaxEntries: c.maxEntries,
+	}
+	if total := st.MemoryHits + st.StoreHits + st.Misses; total > 0 {
+		st.HitRate = float64(st.MemoryHits+st.StoreHits) / float64(total)
+	}
+	return st
+}
+
+// Prune deletes the stored embeddings cached before before. Embeddings in
+// memory stay until they are evicted.
+func (c *EmbeddingCache) Prune(before time.Time) (int64, error) {
+	if c == nil || c.store == nil {
+		return 0, nil
+	}
+	return c.store.DeleteCachedEmbeddings(before)
+}
+
+// lookup returns the cached embeddings of keys, nil for those not cached
+func (c *EmbeddingCache) lookup(keys []string) [][]float32 {
+	vecs := make([][]float32, len(keys))
+	var missing []string
+	c.mu.Lock()
+	for i, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			c.lru.MoveToFront(el)
+			vecs[i] = el.Value.(*embeddingCacheEntry).vec
+			c.memoryHits++
+		} else {
+			missing = append(missing, key)
+		}
+	}
+	c.mu.Unlock()
+	if len(missing) == 0 || c.store == nil {
+		c.countMisses(len(missing))
+		return vecs
+	}
+
+	found, err := c.store.GetCachedEmbeddings(missing)
+	if err != nil {
+		log.Printf("[Memory] Failed to read cached embeddings: %v", err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, key := range keys {
+		if vecs[i] != nil {
+			continue
+		}
+		if vec, ok := found[key]; ok {
+			vecs[i] = vec
+			c.storeHits++
+			c.remember(key, vec)
+		} else {
+			c.misses++
+		}
+	}
+	return vecs
+}
+
+func (c *EmbeddingCache) countMisses(n int) {
+	c.mu.Lock()
+	c.misses += int64(n)
+	c.mu.Unlock()
+}
+
+// add caches embeddings of the given version by key
+func (c *EmbeddingCache) add(version string, entries map[string][]float32) {
+	c.mu.Lock()
+	for key, vec := range entries {
+		c.remember(key, vec)
+	}
+	c.mu.Unlock()
+	if c.store != nil {
+		if err := c.store.StoreCachedEmbeddings(version, entries); err != nil {
+			log.Printf("[Memory] Failed to store cached embeddings: %v", err)
+		}
+	}
+}
+
+// remember puts an embedding in memory, evicting the least recently used
+// past maxEntries. c.mu must be held.
+func (c *EmbeddingCache) remember(key string, vec []float32) {
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*embeddingCacheEntry).vec = vec
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(&embeddingCacheEntry{key: key, vec: vec})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*embeddingCacheEntry).key)
+	}
+}
+
+// cachedEmbedder embeds through an EmbeddingCache
+type cachedEmbedder struct {
+	cache   *EmbeddingCache
+	inner   Embedder
+	version string
+}
+
+// Version returns the wrapped embedder's version
+func (e *cachedEmbedder) Version() string {
+	return e.version
+}
+
+func (e *cachedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	keys := make([]string, len(texts))
+	for i, text := range texts {
+		keys[i] = EmbeddingCacheKey(e.version, text)
+	}
+	vecs := e.cache.lookup(keys)
+
+	var missing []int
+	var missTexts []string
+	for i, vec := range vecs {
+		if vec == nil {
+			missing = append(missing, i)
+			missTexts = append(missTexts, texts[i])
+		}
+	}
+	if len(missing) == 0 {
+		return vecs, nil
+	}
+
+	fresh, err := e.inner.Embed(ctx, missTexts)
+	if err != nil {
+		return nil, err
+	}
+	if len(fresh) != len(missing) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(missing), len(fresh))
+	}
+	entries := make(map[string][]float32, len(missing))
+	for j, i := range missing {
+		vecs[i] = fresh[j]
+		if len(fresh[j]) > 0 {
+			entries[keys[i]] = fresh[j]
+		}
+	}
+	e.cache.add(e.version, entries)
+	return vecs, nil
+}
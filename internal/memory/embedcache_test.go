@@ -0,0 +1,127 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// cacheStore is an EmbeddingCacheStore kept in memory
+type cacheStore struct {
+	entries map[string][]float32
+	writes  int
+}
+
+func (s *cacheStore) GetCachedEmbeddings(keys []string) (map[string][]float32, error) {
+	found := make(map[string][]float32)
+	for _, k := range keys {
+		if vec, ok := s.entries[k]; ok {
+			found[k] = vec
+		}
+	}
+	return found, nil
+}
+
+func (s *cacheStore) StoreCachedEmbeddings(_ string, entries map[string][]float32) error {
+	if s.entries == nil {
+		s.entries = make(map[string][]float32)
+	}
+	for k, vec := range entries {
+		s.entries[k] = vec
+	}
+	s.writes++
+	return nil
+}
+
+func (s *cacheStore) DeleteCachedEmbeddings(time.Time) (int64, error) {
+	n := int64(len(s.entries))
+	s.entries = nil
+	return n, nil
+}
+
+func TestEmbeddingCache(t *testing.T) {
+	ctx := context.Background()
+	store := &cacheStore{}
+	inner := &modelEmbedder{model: "nomic"}
+	cache := NewEmbeddingCache(store, 2)
+	e := cache.Wrap(inner)
+	if EmbeddingVersion(e) != "provider:nomic" {
+		t.Fatalf("expected the wrapped embedder's version, got %q", EmbeddingVersion(e))
+	}
+
+	if vecs, err := e.Embed(ctx, []string{"a", "b"}); err != nil || len(vecs) != 2 || inner.calls != 1 {
+		t.Fatalf("expected both texts embedded by the model, got %v, %v after %d calls", vecs, err, inner.calls)
+	}
+	if vecs, err := e.Embed(ctx, []string{"b", "a"}); err != nil || len(vecs) != 2 || vecs[0] == nil || inner.calls != 1 {
+		t.Fatalf("expected both texts from memory, got %v, %v after %d calls", vecs, err, inner.calls)
+	}
+
+	// "c" evicts "b" from memory, which is then read from the store
+	if _, err := e.Embed(ctx, []string{"c"}); err != nil || inner.calls != 2 {
+		t.Fatalf("expected a new text to be embedded, got %v after %d calls", err, inner.calls)
+	}
+	if _, err := e.Embed(ctx, []string{"b"}); err != nil || inner.calls != 2 {
+		t.Fatalf("expected the evicted text from the store, got %v after %d calls", err, inner.calls)
+	}
+
+	st := cache.Stats()
+	if st.MemoryHits != 2 || st.StoreHits != 1 || st.Misses != 3 || st.HitRate != 0.5 || st.Entries != 2 {
+		t.Errorf("unexpected stats %+v", st)
+	}
+
+	// A restarted server finds what the last one embedded
+	restarted := NewEmbeddingCache(store, 0).Wrap(&modelEmbedder{model: "nomic", fail: true})
+	if _, err := restarted.Embed(ctx, []string{"a", "c"}); err != nil {
+		t.Errorf("expected stored embeddings to survive a restart, got %v", err)
+	}
+	// Another model's embeddings are not shared
+	other := &modelEmbedder{model: "minilm"}
+	if _, err := cache.Wrap(other).Embed(ctx, []string{"a"}); err != nil || other.calls != 1 {
+		t.Errorf("expected another model to embed the text itself, got %v after %d calls", err, other.calls)
+	}
+}
+
+func TestEmbeddingCache_FailedEmbedding(t *testing.T) {
+	store := &cacheStore{}
+	e := NewEmbeddingCache(store, 0).Wrap(&modelEmbedder{model: "nomic", fail: true})
+	if _, err := e.Embed(context.Background(), []string{"a"}); err == nil {
+		t.Fatal("expected the model's error")
+	}
+	if store.writes != 0 {
+		t.Error("expected nothing cached after a failure")
+	}
+}
+
+func TestEmbeddingCache_Wrap(t *testing.T) {
+	cache := NewEmbeddingCache(nil, 0)
+	hash := NewHashEmbedder()
+	if cache.Wrap(hash) != Embedder(hash) {
+		t.Error("expected the hash embedder to be left uncached")
+	}
+	fallback := NewFallbackEmbedder(&modelEmbedder{model: "nomic"})
+	if cache.Wrap(fallback) != Embedder(fallback) {
+		t.Error("expected a fallback embedder to be left uncached")
+	}
+	var none *EmbeddingCache
+	model := &modelEmbedder{model: "nomic"}
+	if none.Wrap(model) != Embedder(model) {
+		t.Error("expected a nil cache to wrap nothing")
+	}
+	if EmbeddingCacheKey("provider:a", "text") == EmbeddingCacheKey("provider:b", "text") {
+		t.Error("expected keys to depend on the version")
+	}
+}
+
+func TestReembedderPrunesCache(t *testing.T) {
+	store := &cacheStore{entries: map[string][]float32{"k": {1}}}
+	cache := NewEmbeddingCache(store, 0)
+	r := NewReembedder(newReembedStore(), cache.Wrap(&modelEmbedder{model: "nomic"}), nil, ReembedConfig{})
+	r.SetCache(cache, time.Hour)
+	r.pruneCache()
+	if len(store.entries) != 0 {
+		t.Error("expected expired embeddings to be pruned")
+	}
+	if st := r.Status(); st.Cache == nil || st.Cache.MaxEntries != DefaultEmbeddingCacheSize {
+		t.Errorf("expected cache stats in the status, got %+v", st.Cache)
+	}
+}
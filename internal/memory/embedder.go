@@ -20,6 +20,32 @@ type Embedder interface {
 	Embed(ctx context.Context, texts []string) ([][]float32, error)
 }
 
+// VersionedEmbedder is an Embedder that names the model behind its vectors.
+// Vectors from different versions are not comparable, so each stored
+// embedding records the version it came from.
+type VersionedEmbedder interface {
+	Embedder
+	Version() string
+}
+
+// EmbeddingVersion returns e's version, or "" if it does not report one
+func EmbeddingVersion(e Embedder) string {
+	if v, ok := e.(VersionedEmbedder); ok {
+		return v.Version()
+	}
+	return ""
+}
+
+// EmbedWithVersion embeds texts with e and returns the version of the
+// vectors, which for a FallbackEmbedder depends on which embedder answered.
+func EmbedWithVersion(ctx context.Context, e Embedder, texts []string) ([][]float32, string, error) {
+	if f, ok := e.(*FallbackEmbedder); ok {
+		return f.embedWithVersion(ctx, texts)
+	}
+	vecs, err := e.Embed(ctx, texts)
+	return vecs, EmbeddingVersion(e), err
+}
+
 // ---- Provider-based embedder (OpenAI-compatible /v1/embeddings) ----
 
 // ProviderEmbedder calls an OpenAI-compatible embedding endpoint.
@@ -40,6 +66,11 @@ func NewProviderEmbedder(endpoint, apiKey, model string) *ProviderEmbedder {
 	}
 }
 
+// Version names the model the endpoint embeds with
+func (e *ProviderEmbedder) Version() string {
+	return "provider:" + e.model
+}
+
 type embeddingRequest struct {
 	Model string   `json:"model"`
 	Input []string `json:"input"`
@@ -102,6 +133,10 @@ func (e *ProviderEmbedder) Embed(ctx context.Context, texts []string) ([][]float
 
 const hashDimensions = 256
 
+// HashEmbeddingVersion is the version of HashEmbedder's vectors. Lessons
+// embedded before versions were recorded carry it.
+const HashEmbeddingVersion = "hash-256"
+
 // HashEmbedder creates fixed-dimension vectors using the hashing trick.
 // Each word is hashed to a position in the vector and TF weights are applied.
 // This provides rough semantic similarity without any external model.
@@ -111,6 +146,11 @@ func NewHashEmbedder() *HashEmbedder {
 	return &HashEmbedder{}
 }
 
+// Version returns HashEmbeddingVersion
+func (e *HashEmbedder) Version() string {
+	return HashEmbeddingVersion
+}
+
 func (e *HashEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
 	result := make([][]float32, len(texts))
 	for i, text := range texts {
@@ -203,6 +243,44 @@ func CosineSimilarity(a, b []float32) float32 {
 	return float32(dot / denom)
 }
 
+// CosineSimilarities computes the cosine similarity of query with each of
+// vectors into out, which must be as long as vectors. The query's norm is
+// computed once, and each product runs four independent accumulators over
+// contiguous memory, which the CPU can pipeline and vectorize. Vectors of
+// another length score 0.
+func CosineSimilarities(query []float32, vectors [][]float32, out []float32) {
+	queryNorm := math.Sqrt(float64(dot(query, query)))
+	for i, vec := range vectors {
+		if len(vec) != len(query) || len(vec) == 0 || queryNorm == 0 {
+			out[i] = 0
+			continue
+		}
+		norm := math.Sqrt(float64(dot(vec, vec)))
+		if norm == 0 {
+			out[i] = 0
+			continue
+		}
+		out[i] = float32(float64(dot(query, vec)) / (queryNorm * norm))
+	}
+}
+
+// dot is the dot product of two vectors of the same length
+func dot(a, b []float32) float32 {
+	b = b[:len(a)]
+	var s0, s1, s2, s3 float32
+	i := 0
+	for ; i+4 <= len(a); i += 4 {
+		s0 += a[i] * b[i]
+		s1 += a[i+1] * b[i+1]
+		s2 += a[i+2] * b[i+2]
+		s3 += a[i+3] * b[i+3]
+	}
+	for ; i < len(a); i++ {
+		s0 += a[i] * b[i]
+	}
+	return (s0 + s1) + (s2 + s3)
+}
+
 func normalize(vec []float32) {
 	var sum float64
 	for _, v := range vec {
@@ -260,12 +338,27 @@ func NewFallbackEmbedder(primary Embedder) *FallbackEmbedder {
 }
 
 func (e *FallbackEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	result, _, err := e.embedWithVersion(ctx, texts)
+	return result, err
+}
+
+// Version returns the primary embedder's version. Vectors from the hash
+// fallback carry HashEmbeddingVersion instead; see EmbedWithVersion.
+func (e *FallbackEmbedder) Version() string {
+	if e.primary != nil {
+		return EmbeddingVersion(e.primary)
+	}
+	return HashEmbeddingVersion
+}
+
+func (e *FallbackEmbedder) embedWithVersion(ctx context.Context, texts []string) ([][]float32, string, error) {
 	if e.primary != nil {
 		result, err := e.primary.Embed(ctx, texts)
 		if err == nil {
-			return result, nil
+			return result, EmbeddingVersion(e.primary), nil
 		}
 		// Fall through to hash embedder
 	}
-	return e.fallback.Embed(ctx, texts)
+	result, err := e.fallback.Embed(ctx, texts)
+	return result, HashEmbeddingVersion, err
 }
@@ -57,11 +57,34 @@ func (e *Extractor) ExtractFromLoop(projectID, beadID string, entries []ActionEn
 		lessons = append(lessons, *insight)
 	}
 
+	e.storeLessons(projectID, beadID, "conversation_insight", lessons)
+}
+
+// CIFailure is one commit of a bead's branch whose CI checks failed
+type CIFailure struct {
+	SHA    string
+	Checks []string // Names of the failed checks
+}
+
+// ExtractFromCI stores a lesson about the CI checks that failed on several
+// commits of a bead's branch in a row. Designed to be called when CI
+// tracking sees a branch fail repeatedly.
+func (e *Extractor) ExtractFromCI(projectID, beadID, branch string, failures []CIFailure) {
+	if e == nil || e.store == nil {
+		return
+	}
+	if l := extractCIPatterns(branch, failures); l != nil {
+		e.storeLessons(projectID, beadID, "ci_failure", []extractedLesson{*l})
+	}
+}
+
+// storeLessons embeds and stores extracted lessons under category
+func (e *Extractor) storeLessons(projectID, beadID, category string, lessons []extractedLesson) {
 	for _, l := range lessons {
 		lesson := &models.Lesson{
 			ID:             uuid.New().String(),
 			ProjectID:      projectID,
-			Category:       "conversation_insight",
+			Category:       category,
 			Title:          l.title,
 			Detail:         l.detail,
 			SourceBeadID:   beadID,
@@ -73,8 +96,9 @@ func (e *Extractor) ExtractFromLoop(projectID, beadID string, entries []ActionEn
 		if e.embedder != nil {
 			text := l.title + " " + l.detail
 			ctx := context.Background()
-			embeddings, err := e.embedder.Embed(ctx, []string{text})
+			embeddings, version, err := EmbedWithVersion(ctx, e.embedder, []string{text})
 			if err == nil && len(embeddings) > 0 && len(embeddings[0]) > 0 {
+				lesson.EmbeddingVersion = version
 				if err := e.store.StoreLessonWithEmbedding(lesson, embeddings[0]); err != nil {
 					log.Printf("[Extractor] Failed to store lesson with embedding: %v", err)
 				} else {
@@ -150,6 +174,34 @@ func extractEditPatterns(entries []ActionEntry) []extractedLesson {
 	return lessons
 }
 
+func extractCIPatterns(branch string, failures []CIFailure) *extractedLesson {
+	if len(failures) < 2 {
+		return nil
+	}
+	counts := make(map[string]int)
+	var checks []string
+	for _, f := range failures {
+		for _, name := range f.Checks {
+			if counts[name] == 0 {
+				checks = append(checks, name)
+			}
+			counts[name]++
+		}
+	}
+	var parts []string
+	for _, name := range checks[:min(len(checks), 5)] {
+		parts = append(parts, fmt.Sprintf("%s (%d times)", name, counts[name]))
+	}
+	detail := fmt.Sprintf("CI failed on %d commits in a row of %s", len(failures), branch)
+	if len(parts) > 0 {
+		detail += ": " + strings.Join(parts, ", ")
+	}
+	return &extractedLesson{
+		title:  fmt.Sprintf("Repeated CI failures (%d commits)", len(failures)),
+		detail: detail + " — run these checks locally before pushing",
+	}
+}
+
 func extractTerminalInsight(reason string, totalActions int) *extractedLesson {
 	switch reason {
 	case "max_iterations":
@@ -415,6 +415,40 @@ func TestExtractTestPatterns_NoTestEntries(t *testing.T) {
 	}
 }
 
+// --- Tests for ExtractFromCI ---
+
+func TestExtractFromCI_RepeatedFailures(t *testing.T) {
+	store := &mockLessonStore{}
+	ext := NewExtractor(store, NewHashEmbedder())
+
+	ext.ExtractFromCI("proj-1", "bead-1", "agent/bead-1/login", []CIFailure{
+		{SHA: "a1", Checks: []string{"lint", "test"}},
+		{SHA: "b2", Checks: []string{"test"}},
+	})
+
+	if len(store.lessons) != 1 {
+		t.Fatalf("expected 1 lesson, got %d", len(store.lessons))
+	}
+	l := store.lessons[0]
+	if l.Category != "ci_failure" || l.SourceBeadID != "bead-1" {
+		t.Errorf("unexpected lesson %+v", l)
+	}
+	if !strings.Contains(l.Detail, "test (2 times)") || !strings.Contains(l.Detail, "agent/bead-1/login") {
+		t.Errorf("expected the detail to name the branch and the failing checks, got %q", l.Detail)
+	}
+}
+
+func TestExtractFromCI_SingleFailure(t *testing.T) {
+	store := &mockLessonStore{}
+	NewExtractor(store, nil).ExtractFromCI("proj-1", "bead-1", "main", []CIFailure{{SHA: "a1", Checks: []string{"test"}}})
+	if len(store.lessons) != 0 {
+		t.Errorf("expected 0 lessons for 1 CI failure, got %d", len(store.lessons))
+	}
+
+	var nilExt *Extractor
+	nilExt.ExtractFromCI("proj-1", "bead-1", "main", nil)
+}
+
 // --- Tests for extractEditPatterns ---
 
 func TestExtractEditPatterns_NoEdits(t *testing.T) {
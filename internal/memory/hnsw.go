@@ -0,0 +1,317 @@
+package memory
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// HNSWConfig tunes an HNSW index. Zero values use the defaults.
+type HNSWConfig struct {
+	M              int // Links per node above the bottom layer, twice as many on it; default 16
+	EfConstruction int // Candidates considered when linking a node; default 200
+	EfSearch       int // Candidates considered when searching; default 64
+}
+
+func (c HNSWConfig) withDefaults() HNSWConfig {
+	if c.M <= 0 {
+		c.M = 16
+	}
+	if c.EfConstruction <= 0 {
+		c.EfConstruction = 200
+	}
+	if c.EfSearch <= 0 {
+		c.EfSearch = 64
+	}
+	return c
+}
+
+// IndexHit is a vector an index found near a query
+type IndexHit struct {
+	ID         string
+	Similarity float32 // Cosine similarity with the query
+}
+
+// HNSW is a hierarchical navigable small world graph: an approximate
+// nearest-neighbor index over vectors by cosine similarity. Searches visit
+// a few hundred vectors instead of all of them, so their cost barely grows
+// with the number indexed. Vectors are added one at a time; removed
+// vectors stay in the graph, unreturned, until Rebuild.
+type HNSW struct {
+	cfg       HNSWConfig
+	levelMult float64
+
+	mu       sync.RWMutex
+	nodes    []*hnswNode
+	ids      map[string]int
+	entry    int
+	maxLevel int
+	deleted  int
+	rng      *rand.Rand
+}
+
+type hnswNode struct {
+	id      string
+	vec     []float32 // Normalized, so similarity is a dot product
+	links   [][]int   // By layer
+	deleted bool
+}
+
+// NewHNSW creates an empty index
+func NewHNSW(cfg HNSWConfig) *HNSW {
+	cfg = cfg.withDefaults()
+	return &HNSW{
+		cfg:       cfg,
+		levelMult: 1 / math.Log(float64(cfg.M)),
+		ids:       make(map[string]int),
+		entry:     -1,
+		rng:       rand.New(rand.NewSource(1)),
+	}
+}
+
+// Len returns how many vectors the index returns
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// Deleted returns how many removed vectors are still in the graph
+func (h *HNSW) Deleted() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.deleted
+}
+
+// Add indexes vec under id, replacing any vector already indexed under it.
+// Zero vectors are not indexed.
+func (h *HNSW) Add(id string, vec []float32) {
+	unit := make([]float32, len(vec))
+	copy(unit, vec)
+	normalize(unit)
+	if dot(unit, unit) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(id)
+	h.insert(id, unit)
+}
+
+// Remove stops returning the vector indexed under id
+func (h *HNSW) Remove(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(id)
+}
+
+func (h *HNSW) remove(id string) {
+	if i, ok := h.ids[id]; ok {
+		h.nodes[i].deleted = true
+		delete(h.ids, id)
+		h.deleted++
+	}
+}
+
+// Rebuild builds the graph again from the vectors it returns, dropping
+// the removed ones
+func (h *HNSW) Rebuild() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	old := h.nodes
+	h.nodes, h.ids, h.entry, h.maxLevel, h.deleted = nil, make(map[string]int), -1, 0, 0
+	for _, n := range old {
+		if !n.deleted {
+			h.insert(n.id, n.vec)
+		}
+	}
+}
+
+func (h *HNSW) insert(id string, unit []float32) {
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	idx := len(h.nodes)
+	node := &hnswNode{id: id, vec: unit, links: make([][]int, level+1)}
+	h.nodes = append(h.nodes, node)
+	h.ids[id] = idx
+	if h.entry < 0 {
+		h.entry, h.maxLevel = idx, level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(unit, ep, l)
+	}
+	eps := []int{ep}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		found := h.searchLayer(unit, eps, h.cfg.EfConstruction, l)
+		maxLinks := h.maxLinks(l)
+		neighbors := found
+		if len(neighbors) > maxLinks {
+			neighbors = neighbors[:maxLinks]
+		}
+		node.links[l] = make([]int, len(neighbors))
+		for i, c := range neighbors {
+			node.links[l][i] = c.node
+			h.link(c.node, idx, l)
+		}
+		eps = eps[:0]
+		for _, c := range found {
+			eps = append(eps, c.node)
+		}
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = idx, level
+	}
+}
+
+func (h *HNSW) maxLinks(level int) int {
+	if level == 0 {
+		return 2 * h.cfg.M
+	}
+	return h.cfg.M
+}
+
+// link adds a link from node from to node to on a layer, keeping the
+// closest links when there are too many
+func (h *HNSW) link(from, to, level int) {
+	n := h.nodes[from]
+	n.links[level] = append(n.links[level], to)
+	if len(n.links[level]) <= h.maxLinks(level) {
+		return
+	}
+	links := make([]hnswCandidate, len(n.links[level]))
+	for i, other := range n.links[level] {
+		links[i] = hnswCandidate{node: other, sim: dot(n.vec, h.nodes[other].vec)}
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].sim > links[j].sim })
+	n.links[level] = n.links[level][:h.maxLinks(level)]
+	for i := range n.links[level] {
+		n.links[level][i] = links[i].node
+	}
+}
+
+// greedy walks a layer from ep to the node closest to q
+func (h *HNSW) greedy(q []float32, ep, level int) int {
+	best := dot(q, h.nodes[ep].vec)
+	for changed := true; changed; {
+		changed = false
+		for _, next := range h.nodes[ep].links[level] {
+			if sim := dot(q, h.nodes[next].vec); sim > best {
+				best, ep, changed = sim, next, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns up to ef nodes of a layer closest to q, most similar
+// first, searching from eps. Removed nodes are still walked through.
+func (h *HNSW) searchLayer(q []float32, eps []int, ef, level int) []hnswCandidate {
+	visited := make(map[int]bool, ef*4)
+	frontier := &hnswQueue{max: true}
+	results := &hnswQueue{}
+	for _, ep := range eps {
+		if visited[ep] {
+			continue
+		}
+		visited[ep] = true
+		c := hnswCandidate{node: ep, sim: dot(q, h.nodes[ep].vec)}
+		heap.Push(frontier, c)
+		heap.Push(results, c)
+	}
+	for results.Len() > ef {
+		heap.Pop(results)
+	}
+
+	for frontier.Len() > 0 {
+		c := heap.Pop(frontier).(hnswCandidate)
+		if results.Len() >= ef && c.sim < results.items[0].sim {
+			break
+		}
+		for _, next := range h.nodes[c.node].links[level] {
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			sim := dot(q, h.nodes[next].vec)
+			if results.Len() < ef || sim > results.items[0].sim {
+				heap.Push(frontier, hnswCandidate{node: next, sim: sim})
+				heap.Push(results, hnswCandidate{node: next, sim: sim})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	found := results.items
+	sort.Slice(found, func(i, j int) bool { return found[i].sim > found[j].sim })
+	return found
+}
+
+// Search returns up to k indexed vectors most similar to query, most
+// similar first
+func (h *HNSW) Search(query []float32, k int) []IndexHit {
+	if k <= 0 {
+		return nil
+	}
+	q := make([]float32, len(query))
+	copy(q, query)
+	normalize(q)
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.entry < 0 || len(q) != len(h.nodes[h.entry].vec) {
+		return nil
+	}
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	// Removed nodes take places among the candidates, so look further
+	ef := max(h.cfg.EfSearch, k)
+	if live := len(h.ids); live > 0 {
+		ef = ef * len(h.nodes) / live
+	}
+	var hits []IndexHit
+	for _, c := range h.searchLayer(q, []int{ep}, ef, 0) {
+		if n := h.nodes[c.node]; !n.deleted {
+			hits = append(hits, IndexHit{ID: n.id, Similarity: c.sim})
+			if len(hits) == k {
+				break
+			}
+		}
+	}
+	return hits
+}
+
+type hnswCandidate struct {
+	node int
+	sim  float32
+}
+
+// hnswQueue is a heap of candidates, least similar on top, or most similar
+// with max set
+type hnswQueue struct {
+	items []hnswCandidate
+	max   bool
+}
+
+func (q *hnswQueue) Len() int { return len(q.items) }
+func (q *hnswQueue) Less(i, j int) bool {
+	if q.max {
+		return q.items[i].sim > q.items[j].sim
+	}
+	return q.items[i].sim < q.items[j].sim
+}
+func (q *hnswQueue) Swap(i, j int)      { q.items[i], q.items[j] = q.items[j], q.items[i] }
+func (q *hnswQueue) Push(x interface{}) { q.items = append(q.items, x.(hnswCandidate)) }
+func (q *hnswQueue) Pop() interface{} {
+	last := q.items[len(q.items)-1]
+	q.items = q.items[:len(q.items)-1]
+	return last
+}
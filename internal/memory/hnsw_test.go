@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomVectors(n, dim int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vecs := make([][]float32, n)
+	for i := range vecs {
+		vecs[i] = make([]float32, dim)
+		for j := range vecs[i] {
+			vecs[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return vecs
+}
+
+func TestCosineSimilarities(t *testing.T) {
+	query := randomVectors(1, 37, 1)[0]
+	vecs := append(randomVectors(5, 37, 2), []float32{1, 2}, make([]float32, 37))
+	out := make([]float32, len(vecs))
+	CosineSimilarities(query, vecs, out)
+	for i, vec := range vecs {
+		if want := CosineSimilarity(query, vec); math.Abs(float64(out[i]-want)) > 1e-5 {
+			t.Errorf("vector %d: got %v, want %v", i, out[i], want)
+		}
+	}
+}
+
+func TestHNSWRecall(t *testing.T) {
+	vecs := randomVectors(1000, 32, 3)
+	h := NewHNSW(HNSWConfig{})
+	for i, vec := range vecs {
+		h.Add(fmt.Sprint(i), vec)
+	}
+
+	const k = 10
+	found, total := 0, 0
+	sims := make([]float32, len(vecs))
+	for _, q := range randomVectors(20, 32, 4) {
+		CosineSimilarities(q, vecs, sims)
+		order := make([]int, len(vecs))
+		for i := range order {
+			order[i] = i
+		}
+		sort.Slice(order, func(a, b int) bool { return sims[order[a]] > sims[order[b]] })
+		want := make(map[string]bool, k)
+		for _, i := range order[:k] {
+			want[fmt.Sprint(i)] = true
+		}
+		hits := h.Search(q, k)
+		for _, hit := range hits {
+			if want[hit.ID] {
+				found++
+			}
+		}
+		total += k
+	}
+	if recall := float64(found) / float64(total); recall < 0.9 {
+		t.Errorf("expected recall of at least 0.9, got %.2f", recall)
+	}
+}
+
+func TestHNSWRemoveAndRebuild(t *testing.T) {
+	h := NewHNSW(HNSWConfig{M: 4})
+	for i, vec := range randomVectors(100, 8, 5) {
+		h.Add(fmt.Sprint(i), vec)
+	}
+	target := []float32{1, 1, 1, 1, 1, 1, 1, 1}
+	h.Add("target", target)
+	if hits := h.Search(target, 1); len(hits) != 1 || hits[0].ID != "target" || hits[0].Similarity < 0.999 {
+		t.Fatalf("expected the identical vector first, got %+v", hits)
+	}
+
+	h.Remove("target")
+	if hits := h.Search(target, 5); len(hits) != 5 || hits[0].ID == "target" {
+		t.Errorf("expected removed vectors to be skipped, got %+v", hits)
+	}
+	if h.Len() != 100 || h.Deleted() != 1 {
+		t.Errorf("expected 100 live and 1 removed, got %d and %d", h.Len(), h.Deleted())
+	}
+	h.Rebuild()
+	if h.Len() != 100 || h.Deleted() != 0 {
+		t.Errorf("expected the rebuild to drop removed vectors, got %d and %d", h.Len(), h.Deleted())
+	}
+
+	h.Add("zero", make([]float32, 8))
+	if h.Len() != 100 {
+		t.Error("expected zero vectors not to be indexed")
+	}
+}
@@ -0,0 +1,176 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// LessonIndexStore lists the embedded lessons a LessonIndex indexes
+type LessonIndexStore interface {
+	// ListLessonEmbeddingVersions returns the embedding version of each of
+	// a project's current embedded lessons, by lesson ID
+	ListLessonEmbeddingVersions(projectID string) (map[string]string, error)
+	GetLessonEmbeddings(ids []string) (map[string][]float32, error)
+}
+
+// LessonIndexConfig configures a LessonIndex. Zero values use the defaults.
+type LessonIndexConfig struct {
+	MinLessons      int           // Embedded lessons a project needs to be indexed; default 2000
+	RefreshInterval time.Duration // Least time between refreshes of a project's index; default 1m
+	HNSW            HNSWConfig
+}
+
+func (c LessonIndexConfig) withDefaults() LessonIndexConfig {
+	if c.MinLessons <= 0 {
+		c.MinLessons = 2000
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = time.Minute
+	}
+	return c
+}
+
+// lessonIndexBatch is how many embeddings a refresh reads at a time
+const lessonIndexBatch = 500
+
+// LessonIndex keeps an HNSW index of the embedded lessons of each project
+// large enough to need one, one graph per embedding version. A search
+// first refreshes the project's index, at most once per refresh interval,
+// by reading only the lessons added, re-embedded or superseded since the
+// last refresh.
+type LessonIndex struct {
+	store LessonIndexStore
+	cfg   LessonIndexConfig
+
+	mu       sync.Mutex
+	projects map[string]*projectLessonIndex
+}
+
+type projectLessonIndex struct {
+	mu        sync.Mutex // Held while refreshing
+	refreshed time.Time
+	size      int               // Embedded lessons at the last refresh
+	versions  map[string]string // Embedding version of each indexed lesson
+	graphs    map[string]*HNSW  // By embedding version
+}
+
+// NewLessonIndex creates an index reading lessons from store
+func NewLessonIndex(store LessonIndexStore, cfg LessonIndexConfig) *LessonIndex {
+	return &LessonIndex{
+		store:    store,
+		cfg:      cfg.withDefaults(),
+		projects: make(map[string]*projectLessonIndex),
+	}
+}
+
+// Search returns the IDs of up to k of the project's lessons most similar
+// to the queries, most similar first. Each lesson is compared with the
+// query of its embedding version, or the query under "" when there is
+// none. It returns false when the project has too few lessons to be
+// indexed, and they should be compared one by one instead.
+func (x *LessonIndex) Search(projectID string, queries map[string][]float32, k int) ([]string, bool, error) {
+	if x == nil {
+		return nil, false, nil
+	}
+	x.mu.Lock()
+	p, ok := x.projects[projectID]
+	if !ok {
+		p = &projectLessonIndex{}
+		x.projects[projectID] = p
+	}
+	x.mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if time.Since(p.refreshed) >= x.cfg.RefreshInterval {
+		if err := x.refresh(p, projectID); err != nil {
+			return nil, false, err
+		}
+	}
+	if p.size < x.cfg.MinLessons {
+		return nil, false, nil
+	}
+
+	var hits []IndexHit
+	for version, graph := range p.graphs {
+		query, ok := queries[version]
+		if !ok {
+			query = queries[""]
+		}
+		if len(query) > 0 {
+			hits = append(hits, graph.Search(query, k)...)
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Similarity > hits[j].Similarity })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	return ids, true, nil
+}
+
+// refresh brings p up to date with the project's lessons. Projects below
+// the minimum keep no graphs. p.mu must be held.
+func (x *LessonIndex) refresh(p *projectLessonIndex, projectID string) error {
+	current, err := x.store.ListLessonEmbeddingVersions(projectID)
+	if err != nil {
+		return err
+	}
+	p.size = len(current)
+	if p.size < x.cfg.MinLessons {
+		p.refreshed, p.versions, p.graphs = time.Now(), nil, nil
+		return nil
+	}
+	if p.graphs == nil {
+		p.versions, p.graphs = make(map[string]string), make(map[string]*HNSW)
+	}
+
+	// Superseded, deleted and re-embedded lessons leave their graphs
+	for id, version := range p.versions {
+		if current[id] != version {
+			p.graphs[version].Remove(id)
+			delete(p.versions, id)
+		}
+	}
+	var added []string
+	for id := range current {
+		if _, ok := p.versions[id]; !ok {
+			added = append(added, id)
+		}
+	}
+	for start := 0; start < len(added); start += lessonIndexBatch {
+		batch := added[start:min(start+lessonIndexBatch, len(added))]
+		embeddings, err := x.store.GetLessonEmbeddings(batch)
+		if err != nil {
+			return err
+		}
+		for _, id := range batch {
+			vec, version := embeddings[id], current[id]
+			if len(vec) == 0 {
+				continue
+			}
+			graph, ok := p.graphs[version]
+			if !ok {
+				graph = NewHNSW(x.cfg.HNSW)
+				p.graphs[version] = graph
+			}
+			graph.Add(id, vec)
+			p.versions[id] = version
+		}
+	}
+
+	for version, graph := range p.graphs {
+		switch {
+		case graph.Len() == 0:
+			delete(p.graphs, version)
+		case graph.Deleted() > graph.Len():
+			graph.Rebuild()
+		}
+	}
+	p.refreshed = time.Now()
+	return nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// indexStore is a LessonIndexStore kept in memory
+type indexStore struct {
+	versions   map[string]string
+	embeddings map[string][]float32
+	read       int // Embeddings read
+}
+
+func (s *indexStore) ListLessonEmbeddingVersions(string) (map[string]string, error) {
+	out := make(map[string]string, len(s.versions))
+	for id, v := range s.versions {
+		out[id] = v
+	}
+	return out, nil
+}
+
+func (s *indexStore) GetLessonEmbeddings(ids []string) (map[string][]float32, error) {
+	out := make(map[string][]float32, len(ids))
+	for _, id := range ids {
+		out[id] = s.embeddings[id]
+		s.read++
+	}
+	return out, nil
+}
+
+func TestLessonIndex(t *testing.T) {
+	store := &indexStore{versions: map[string]string{}, embeddings: map[string][]float32{}}
+	for i, vec := range randomVectors(50, 8, 6) {
+		id := fmt.Sprint("lesson-", i)
+		store.versions[id], store.embeddings[id] = "provider:a", vec
+	}
+	x := NewLessonIndex(store, LessonIndexConfig{MinLessons: 60, RefreshInterval: time.Nanosecond})
+	query := map[string][]float32{"provider:a": {1, 0, 0, 0, 0, 0, 0, 0}}
+
+	if _, ok, err := x.Search("p", query, 5); ok || err != nil {
+		t.Fatalf("expected a small project not to be indexed, got %v, %v", ok, err)
+	}
+
+	for i, vec := range randomVectors(20, 8, 7) {
+		id := fmt.Sprint("new-", i)
+		store.versions[id], store.embeddings[id] = "provider:a", vec
+	}
+	store.versions["match"], store.embeddings["match"] = "provider:b", []float32{1, 0, 0, 0, 0, 0, 0, 0}
+	query[""] = []float32{1, 0, 0, 0, 0, 0, 0, 0}
+	ids, ok, err := x.Search("p", query, 5)
+	if !ok || err != nil || len(ids) != 5 || ids[0] != "match" {
+		t.Fatalf("expected the index to find the match through the query of any version, got %v, %v, %v", ids, ok, err)
+	}
+	if store.read != 71 {
+		t.Errorf("expected every embedding read once, got %d reads", store.read)
+	}
+
+	// Only changes are read again
+	delete(store.versions, "match")
+	store.versions["new-0"] = "provider:b"
+	if ids, _, _ := x.Search("p", query, 5); len(ids) == 0 || ids[0] == "match" {
+		t.Errorf("expected the superseded lesson to leave the index, got %v", ids)
+	}
+	if store.read != 72 {
+		t.Errorf("expected only the re-embedded lesson to be read, got %d reads", store.read)
+	}
+}
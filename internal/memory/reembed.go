@@ -0,0 +1,357 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// ReembedStore is the subset of database.Database the re-embedding job needs.
+type ReembedStore interface {
+	ListLessonsToReembed(version string, limit int) ([]*models.Lesson, error)
+	UpdateLessonEmbedding(id string, embedding []float32, version string) error
+	CountLessonEmbeddings() (map[string]int, error)
+}
+
+// ReembedConfig configures the re-embedding job
+type ReembedConfig struct {
+	Rate      float64       // Lessons embedded per second
+	BatchSize int           // Lessons per embedding request
+	Interval  time.Duration // How often to look for lessons to re-embed
+}
+
+// DefaultReembedConfig returns the defaults: 2 lessons a second in batches
+// of 16, checked every 10 minutes
+func DefaultReembedConfig() ReembedConfig {
+	return ReembedConfig{Rate: 2, BatchSize: 16, Interval: 10 * time.Minute}
+}
+
+// ReembedStatus reports how far a migration to a new embedder has got
+type ReembedStatus struct {
+	Version    string         `json:"version"`
+	Legacy     []string       `json:"legacy"`  // Versions still read until their lessons are re-embedded
+	Counts     map[string]int `json:"counts"`  // Lessons by embedding version, "" for none
+	Pending    int            `json:"pending"` // Lessons still to embed with Version
+	Reembedded int            `json:"reembedded"`
+	LastRun    *time.Time     `json:"last_run,omitempty"`
+	LastError  string         `json:"last_error,omitempty"`
+
+	Cache *EmbeddingCacheStats `json:"cache,omitempty"` // Without a cache, nil
+}
+
+// Reembedder moves stored lessons to the current embedder. When the
+// embedder changes, lessons embedded by the old one are re-embedded in
+// rate-limited batches, and until they all are, queries are embedded by
+// both so every lesson is compared with a query from its own model.
+type Reembedder struct {
+	store   ReembedStore
+	current Embedder
+	version string
+	legacy  map[string]Embedder
+	cfg     ReembedConfig
+
+	cache    *EmbeddingCache
+	cacheTTL time.Duration
+
+	runMu      sync.Mutex
+	mu         sync.Mutex
+	counts     map[string]int
+	reembedded int
+	lastRun    *time.Time
+	lastErr    string
+	stop       chan struct{}
+}
+
+// NewReembedder returns a job embedding lessons with current. Legacy
+// embedders read lessons they embedded until those are re-embedded; the
+// hash embedder is always among them, since lessons stored before versions
+// were recorded came from it. It returns nil without a store or when
+// current does not report its version.
+func NewReembedder(store ReembedStore, current Embedder, legacy []Embedder, cfg ReembedConfig) *Reembedder {
+	version := EmbeddingVersion(current)
+	if store == nil || current == nil || version == "" {
+		return nil
+	}
+	def := DefaultReembedConfig()
+	if cfg.Rate <= 0 {
+		cfg.Rate = def.Rate
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = def.BatchSize
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = def.Interval
+	}
+	r := &Reembedder{
+		store:   store,
+		current: current,
+		version: version,
+		legacy:  make(map[string]Embedder),
+		cfg:     cfg,
+	}
+	for _, e := range append(legacy, NewHashEmbedder()) {
+		if v := EmbeddingVersion(e); v != "" && v != version {
+			if _, ok := r.legacy[v]; !ok {
+				r.legacy[v] = e
+			}
+		}
+	}
+	return r
+}
+
+// SetCache reports the hit rate of the cache the job's embedders were
+// wrapped with, and deletes its stored embeddings once they are older than
+// ttl on each run; ttl 0 keeps them
+func (r *Reembedder) SetCache(c *EmbeddingCache, ttl time.Duration) {
+	if r == nil || c == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache, r.cacheTTL = c, ttl
+}
+
+// Version returns the version lessons are being moved to
+func (r *Reembedder) Version() string {
+	if r == nil {
+		return ""
+	}
+	return r.version
+}
+
+// Embed embeds texts with the current embedder and returns their version
+func (r *Reembedder) Embed(ctx context.Context, texts []string) ([][]float32, string, error) {
+	return EmbedWithVersion(ctx, r.current, texts)
+}
+
+// QueryEmbeddings embeds a search query once for the current embedder and
+// once for each legacy embedder that still has lessons stored, keyed by
+// version, for SearchLessonsByEmbeddings. It fails only if no embedder
+// could embed the query.
+func (r *Reembedder) QueryEmbeddings(ctx context.Context, text string) (map[string][]float32, error) {
+	queries := make(map[string][]float32)
+	vecs, version, firstErr := r.Embed(ctx, []string{text})
+	if firstErr == nil && len(vecs) > 0 && len(vecs[0]) > 0 {
+		queries[version] = vecs[0]
+	}
+
+	r.mu.Lock()
+	counts := r.counts
+	r.mu.Unlock()
+	for v, e := range r.legacy {
+		if _, done := queries[v]; done {
+			continue
+		}
+		// Before the first count every legacy version might have lessons
+		if counts != nil && counts[v] == 0 {
+			continue
+		}
+		vecs, err := e.Embed(ctx, []string{text})
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if len(vecs) > 0 && len(vecs[0]) > 0 {
+			queries[v] = vecs[0]
+		}
+	}
+	if len(queries) == 0 {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no embedding for the query")
+		}
+		return nil, firstErr
+	}
+	return queries, nil
+}
+
+// RunOnce re-embeds every lesson not yet embedded with the current
+// embedder, no faster than the configured rate, and returns how many it
+// re-embedded. It stops at the first failure, leaving the rest for the
+// next run.
+func (r *Reembedder) RunOnce(ctx context.Context) (int, error) {
+	if r == nil {
+		return 0, nil
+	}
+	r.runMu.Lock()
+	defer r.runMu.Unlock()
+
+	n, err := r.run(ctx)
+	now := time.Now()
+	r.mu.Lock()
+	r.reembedded += n
+	r.lastRun = &now
+	r.lastErr = ""
+	if err != nil {
+		r.lastErr = err.Error()
+	}
+	r.mu.Unlock()
+	r.refreshCounts()
+	return n, err
+}
+
+func (r *Reembedder) run(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		batch, err := r.store.ListLessonsToReembed(r.version, r.cfg.BatchSize)
+		if err != nil {
+			return n, err
+		}
+		if len(batch) == 0 {
+			return n, nil
+		}
+
+		texts := make([]string, len(batch))
+		for i, l := range batch {
+			texts[i] = l.Title + " " + l.Detail
+		}
+		vecs, version, err := r.Embed(ctx, texts)
+		if err != nil {
+			return n, fmt.Errorf("embedding %d lessons: %w", len(batch), err)
+		}
+		// A fallback embedder answering would leave the lessons pending
+		if version != r.version {
+			return n, fmt.Errorf("embedder fell back to %s", version)
+		}
+		if len(vecs) != len(batch) {
+			return n, fmt.Errorf("expected %d embeddings, got %d", len(batch), len(vecs))
+		}
+		for i, l := range batch {
+			if err := r.store.UpdateLessonEmbedding(l.ID, vecs[i], r.version); err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(batch) < r.cfg.BatchSize {
+			return n, nil
+		}
+
+		wait := time.Duration(float64(len(batch)) / r.cfg.Rate * float64(time.Second))
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case <-r.stopped():
+			return n, nil
+		case <-time.After(wait):
+		}
+	}
+}
+
+func (r *Reembedder) stopped() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stop
+}
+
+func (r *Reembedder) refreshCounts() {
+	counts, err := r.store.CountLessonEmbeddings()
+	if err != nil {
+		log.Printf("[Memory] Failed to count lesson embeddings: %v", err)
+		return
+	}
+	r.mu.Lock()
+	r.counts = counts
+	r.mu.Unlock()
+}
+
+// Status counts the stored lessons by embedding version and reports the
+// job's progress
+func (r *Reembedder) Status() ReembedStatus {
+	if r == nil {
+		return ReembedStatus{}
+	}
+	r.refreshCounts()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	st := ReembedStatus{
+		Version:    r.version,
+		Counts:     make(map[string]int, len(r.counts)),
+		Reembedded: r.reembedded,
+		LastRun:    r.lastRun,
+		LastError:  r.lastErr,
+	}
+	if r.cache != nil {
+		stats := r.cache.Stats()
+		st.Cache = &stats
+	}
+	for v := range r.legacy {
+		st.Legacy = append(st.Legacy, v)
+	}
+	sort.Strings(st.Legacy)
+	for v, n := range r.counts {
+		st.Counts[v] = n
+		if v != r.version {
+			st.Pending += n
+		}
+	}
+	return st
+}
+
+// Start runs the job now and then on the configured interval
+func (r *Reembedder) Start(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	if r.stop != nil {
+		r.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	r.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(r.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if n, err := r.RunOnce(ctx); err != nil {
+				log.Printf("[Memory] Re-embedding lessons with %s failed after %d: %v", r.version, n, err)
+			} else if n > 0 {
+				log.Printf("[Memory] Re-embedded %d lessons with %s", n, r.version)
+			}
+			r.pruneCache()
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// pruneCache deletes the stored embeddings older than the cache's TTL
+func (r *Reembedder) pruneCache() {
+	r.mu.Lock()
+	cache, ttl := r.cache, r.cacheTTL
+	r.mu.Unlock()
+	if cache == nil || ttl <= 0 {
+		return
+	}
+	if n, err := cache.Prune(time.Now().Add(-ttl)); err != nil {
+		log.Printf("[Memory] Failed to prune cached embeddings: %v", err)
+	} else if n > 0 {
+		log.Printf("[Memory] Pruned %d cached embeddings", n)
+	}
+}
+
+// Close stops the job
+func (r *Reembedder) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
@@ -0,0 +1,207 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// reembedStore is a ReembedStore kept in memory
+type reembedStore struct {
+	lessons []*models.Lesson
+}
+
+func (s *reembedStore) ListLessonsToReembed(version string, limit int) ([]*models.Lesson, error) {
+	var list []*models.Lesson
+	for _, l := range s.lessons {
+		if l.EmbeddingVersion != version && len(list) < limit {
+			cp := *l
+			list = append(list, &cp)
+		}
+	}
+	return list, nil
+}
+
+func (s *reembedStore) UpdateLessonEmbedding(id string, embedding []float32, version string) error {
+	for _, l := range s.lessons {
+		if l.ID == id {
+			l.Embedding, l.EmbeddingVersion = embedding, version
+		}
+	}
+	return nil
+}
+
+func (s *reembedStore) CountLessonEmbeddings() (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, l := range s.lessons {
+		counts[l.EmbeddingVersion]++
+	}
+	return counts, nil
+}
+
+// modelEmbedder is a versioned embedder with 3 dimensions that can fail
+type modelEmbedder struct {
+	model string
+	fail  bool
+	calls int
+}
+
+func (e *modelEmbedder) Version() string { return "provider:" + e.model }
+
+func (e *modelEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	e.calls++
+	if e.fail {
+		return nil, errors.New("endpoint down")
+	}
+	vecs := make([][]float32, len(texts))
+	for i := range texts {
+		vecs[i] = []float32{1, 0, 0}
+	}
+	return vecs, nil
+}
+
+func newReembedStore(versions ...string) *reembedStore {
+	s := &reembedStore{}
+	for i, v := range versions {
+		s.lessons = append(s.lessons, &models.Lesson{
+			ID: string(rune('a' + i)), Title: "lesson", Detail: "detail", EmbeddingVersion: v,
+		})
+	}
+	return s
+}
+
+func TestEmbedWithVersion(t *testing.T) {
+	ctx := context.Background()
+	if _, v, _ := EmbedWithVersion(ctx, NewHashEmbedder(), []string{"x"}); v != HashEmbeddingVersion {
+		t.Errorf("expected %s, got %q", HashEmbeddingVersion, v)
+	}
+
+	primary := &modelEmbedder{model: "m1"}
+	f := NewFallbackEmbedder(primary)
+	if _, v, _ := EmbedWithVersion(ctx, f, []string{"x"}); v != "provider:m1" {
+		t.Errorf("expected the primary's version, got %q", v)
+	}
+	primary.fail = true
+	vecs, v, err := EmbedWithVersion(ctx, f, []string{"x"})
+	if err != nil || v != HashEmbeddingVersion || len(vecs[0]) != hashDimensions {
+		t.Errorf("expected hash vectors from the fallback, got version %q, err %v", v, err)
+	}
+	if f.Version() != "provider:m1" {
+		t.Errorf("expected the fallback embedder to report the primary's version, got %q", f.Version())
+	}
+}
+
+func TestReembedderMovesLessonsToTheCurrentVersion(t *testing.T) {
+	store := newReembedStore(HashEmbeddingVersion, HashEmbeddingVersion, "", "provider:m1", "provider:m2")
+	current := &modelEmbedder{model: "m2"}
+	r := NewReembedder(store, current, nil, ReembedConfig{Rate: 1000, BatchSize: 2})
+
+	n, err := r.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("expected 4 lessons re-embedded, got %d", n)
+	}
+	if current.calls != 2 {
+		t.Errorf("expected 2 batched requests, got %d", current.calls)
+	}
+	st := r.Status()
+	if st.Pending != 0 || st.Counts["provider:m2"] != 5 || st.Reembedded != 4 {
+		t.Errorf("unexpected status %+v", st)
+	}
+
+	// Nothing is left on a second run
+	if n, _ := r.RunOnce(context.Background()); n != 0 {
+		t.Errorf("expected nothing to re-embed, got %d", n)
+	}
+}
+
+func TestReembedderIsRateLimited(t *testing.T) {
+	store := newReembedStore("", "", "", "")
+	r := NewReembedder(store, &modelEmbedder{model: "m"}, nil, ReembedConfig{Rate: 20, BatchSize: 2})
+
+	start := time.Now()
+	if _, err := r.RunOnce(context.Background()); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	// Two full batches of 2 at 20 a second wait 100ms after each
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the run to be rate limited, took %v", elapsed)
+	}
+}
+
+func TestReembedderStopsWhenTheEmbedderFallsBack(t *testing.T) {
+	store := newReembedStore(HashEmbeddingVersion, HashEmbeddingVersion)
+	primary := &modelEmbedder{model: "m", fail: true}
+	r := NewReembedder(store, NewFallbackEmbedder(primary), nil, ReembedConfig{Rate: 1000})
+
+	n, err := r.RunOnce(context.Background())
+	if err == nil || n != 0 {
+		t.Fatalf("expected the run to stop without re-embedding, got %d, %v", n, err)
+	}
+	st := r.Status()
+	if st.Pending != 2 || st.LastError == "" {
+		t.Errorf("expected the lessons to stay pending with the error reported, got %+v", st)
+	}
+}
+
+func TestReembedderQueryEmbeddingsReadsEveryStoredVersion(t *testing.T) {
+	ctx := context.Background()
+	store := newReembedStore(HashEmbeddingVersion, "provider:m2")
+	previous := &modelEmbedder{model: "m1"}
+	r := NewReembedder(store, &modelEmbedder{model: "m2"}, []Embedder{previous}, ReembedConfig{Rate: 1000})
+
+	// Before anything is counted, every legacy version is embedded
+	queries, err := r.QueryEmbeddings(ctx, "build failure")
+	if err != nil {
+		t.Fatalf("QueryEmbeddings failed: %v", err)
+	}
+	if got := versions(queries); len(got) != 3 {
+		t.Errorf("expected queries for all 3 versions, got %v", got)
+	}
+
+	// Once counted, only versions with lessons stored are
+	r.Status()
+	queries, _ = r.QueryEmbeddings(ctx, "build failure")
+	if got := versions(queries); len(got) != 2 || queries[HashEmbeddingVersion] == nil || queries["provider:m2"] == nil {
+		t.Errorf("expected queries for the hash and current versions, got %v", got)
+	}
+
+	// After the migration only the current version is left
+	if _, err := r.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+	queries, _ = r.QueryEmbeddings(ctx, "build failure")
+	if got := versions(queries); len(got) != 1 || queries["provider:m2"] == nil {
+		t.Errorf("expected only the current version's query, got %v", got)
+	}
+}
+
+func TestNewReembedderNeedsAVersionedEmbedder(t *testing.T) {
+	if r := NewReembedder(newReembedStore(), unversioned{}, nil, ReembedConfig{}); r != nil {
+		t.Error("expected no job for an embedder without a version")
+	}
+	if r := NewReembedder(nil, NewHashEmbedder(), nil, ReembedConfig{}); r != nil {
+		t.Error("expected no job without a store")
+	}
+}
+
+type unversioned struct{}
+
+func (unversioned) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	return make([][]float32, len(texts)), nil
+}
+
+func versions(queries map[string][]float32) []string {
+	var list []string
+	for v := range queries {
+		list = append(list, v)
+	}
+	sort.Strings(list)
+	return list
+}
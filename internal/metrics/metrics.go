@@ -4,6 +4,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"sync"
+	"time"
 )
 
 // Metrics holds all Prometheus metrics for Loom
@@ -22,12 +23,14 @@ type Metrics struct {
 	BeadTransitions *prometheus.CounterVec
 
 	// Provider metrics
-	ProvidersTotal   *prometheus.GaugeVec
-	ProviderRequests *prometheus.CounterVec
-	ProviderErrors   *prometheus.CounterVec
-	ProviderLatency  *prometheus.HistogramVec
-	ProviderTokens   *prometheus.CounterVec
-	ProviderCost     *prometheus.CounterVec
+	ProvidersTotal    *prometheus.GaugeVec
+	ProviderRequests  *prometheus.CounterVec
+	ProviderErrors    *prometheus.CounterVec
+	ProviderLatency   *prometheus.HistogramVec
+	ProviderTokens    *prometheus.CounterVec
+	ProviderCost      *prometheus.CounterVec
+	ProviderQueueWait *prometheus.HistogramVec
+	ProviderQueueShed *prometheus.CounterVec
 
 	// Workflow metrics
 	WorkflowsTotal     *prometheus.GaugeVec
@@ -35,13 +38,22 @@ type Metrics struct {
 	WorkflowDuration   *prometheus.HistogramVec
 	WorkflowErrors     *prometheus.CounterVec
 
+	// Dispatcher metrics
+	DispatchDuration  *prometheus.HistogramVec
+	LoopDetectorTrips *prometheus.CounterVec
+
+	// Prompt budget metrics
+	PromptSectionCuts *prometheus.CounterVec
+	PromptTokensCut   *prometheus.CounterVec
+
 	// System metrics
 	DatabaseConnections prometheus.Gauge
-	CacheHits           prometheus.Counter
-	CacheMisses         prometheus.Counter
 	EventsPublished     *prometheus.CounterVec
 	HTTPRequestsTotal   *prometheus.CounterVec
 	HTTPRequestDuration *prometheus.HistogramVec
+
+	// Bead queue depth and cache statistics, read on every scrape
+	sources *sourceCollector
 }
 
 var (
@@ -166,6 +178,21 @@ func NewMetrics() *Metrics {
 				},
 				[]string{"provider_id", "model", "user_id"},
 			),
+			ProviderQueueWait: promauto.NewHistogramVec(
+				prometheus.HistogramOpts{
+					Name:    "loom_provider_queue_wait_seconds",
+					Help:    "Time requests waited for a free provider slot, by priority lane",
+					Buckets: prometheus.ExponentialBuckets(0.01, 2, 14), // 10ms to 82s
+				},
+				[]string{"provider_id", "lane"},
+			),
+			ProviderQueueShed: promauto.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: "loom_provider_queue_shed_total",
+					Help: "Total requests shed because a provider queue was full",
+				},
+				[]string{"provider_id", "lane"},
+			),
 
 			// Workflow metrics
 			WorkflowsTotal: promauto.NewGaugeVec(
@@ -198,23 +225,44 @@ func NewMetrics() *Metrics {
 				[]string{"workflow_type", "error_type"},
 			),
 
-			// System metrics
-			DatabaseConnections: promauto.NewGauge(
-				prometheus.GaugeOpts{
-					Name: "loom_database_connections",
-					Help: "Number of active database connections",
+			// Dispatcher metrics
+			DispatchDuration: promauto.NewHistogramVec(
+				prometheus.HistogramOpts{
+					Name:    "loom_dispatch_duration_seconds",
+					Help:    "Time from dispatching a bead to its agent finishing in seconds",
+					Buckets: prometheus.ExponentialBuckets(1, 2, 12), // 1s to 68min
 				},
+				[]string{"project_id", "result"},
 			),
-			CacheHits: promauto.NewCounter(
+			LoopDetectorTrips: promauto.NewCounterVec(
 				prometheus.CounterOpts{
-					Name: "loom_cache_hits_total",
-					Help: "Total number of cache hits",
+					Name: "loom_loop_detector_trips_total",
+					Help: "Total number of beads the loop detector flagged",
 				},
+				[]string{"project_id", "detector"}, // detector: progress, history
 			),
-			CacheMisses: promauto.NewCounter(
+
+			// Prompt budget metrics
+			PromptSectionCuts: promauto.NewCounterVec(
 				prometheus.CounterOpts{
-					Name: "loom_cache_misses_total",
-					Help: "Total number of cache misses",
+					Name: "loom_prompt_section_cuts_total",
+					Help: "Total number of prompt sections trimmed or dropped to fit a model's context budget",
+				},
+				[]string{"model", "section", "action"}, // action: trimmed, dropped
+			),
+			PromptTokensCut: promauto.NewCounterVec(
+				prometheus.CounterOpts{
+					Name: "loom_prompt_tokens_cut_total",
+					Help: "Total estimated tokens cut from prompt sections to fit a model's context budget",
+				},
+				[]string{"model", "section"},
+			),
+
+			// System metrics
+			DatabaseConnections: promauto.NewGauge(
+				prometheus.GaugeOpts{
+					Name: "loom_database_connections",
+					Help: "Number of active database connections",
 				},
 			),
 			EventsPublished: promauto.NewCounterVec(
@@ -239,7 +287,9 @@ func NewMetrics() *Metrics {
 				},
 				[]string{"method", "path"},
 			),
+			sources: newSourceCollector(),
 		}
+		prometheus.MustRegister(sharedMetrics.sources)
 	})
 
 	return sharedMetrics
@@ -256,6 +306,51 @@ func (m *Metrics) RecordProviderRequest(providerID, model string, success bool,
 	if tokens > 0 {
 		m.ProviderTokens.WithLabelValues(providerID, model, "total").Add(float64(tokens))
 	}
+	if !success {
+		m.ProviderErrors.WithLabelValues(providerID, "request_failed").Inc()
+	}
+}
+
+// RecordProviderCost records the estimated cost of a provider request
+func (m *Metrics) RecordProviderCost(providerID, model, userID string, costUSD float64) {
+	if costUSD > 0 {
+		m.ProviderCost.WithLabelValues(providerID, model, userID).Add(costUSD * 100)
+	}
+}
+
+// RecordProviderQueue records a request that waited wait for a provider
+// slot in lane, or was shed
+func (m *Metrics) RecordProviderQueue(providerID, lane string, wait time.Duration, shed bool) {
+	if shed {
+		m.ProviderQueueShed.WithLabelValues(providerID, lane).Inc()
+		return
+	}
+	m.ProviderQueueWait.WithLabelValues(providerID, lane).Observe(wait.Seconds())
+}
+
+// RecordDispatch records how long a dispatched bead took to run
+func (m *Metrics) RecordDispatch(projectID string, success bool, duration time.Duration) {
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	m.DispatchDuration.WithLabelValues(projectID, result).Observe(duration.Seconds())
+}
+
+// RecordLoopTrip records the loop detector flagging a bead
+func (m *Metrics) RecordLoopTrip(projectID, detector string) {
+	m.LoopDetectorTrips.WithLabelValues(projectID, detector).Inc()
+}
+
+// RecordPromptCut records a prompt section trimmed from tokens to kept
+// tokens, or dropped when kept is 0
+func (m *Metrics) RecordPromptCut(model, section string, tokens, kept int) {
+	action := "trimmed"
+	if kept == 0 {
+		action = "dropped"
+	}
+	m.PromptSectionCuts.WithLabelValues(model, section, action).Inc()
+	m.PromptTokensCut.WithLabelValues(model, section).Add(float64(tokens - kept))
 }
 
 // RecordBeadTransition records a bead status transition
@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// CacheStats is the part of the response cache's statistics exported as
+// metrics
+type CacheStats struct {
+	Hits    int64
+	Misses  int64
+	HitRate float64
+}
+
+// EmbeddingCacheStats is the part of the embedding cache's statistics
+// exported as metrics
+type EmbeddingCacheStats struct {
+	MemoryHits int64
+	StoreHits  int64
+	Misses     int64
+	HitRate    float64
+}
+
+// sourceCollector exports values owned by other components, reading them
+// on every scrape so they are never stale
+type sourceCollector struct {
+	mu         sync.RWMutex
+	queueDepth func() map[string]int
+	cacheStats func() CacheStats
+	embedStats func() EmbeddingCacheStats
+
+	queueDepthDesc *prometheus.Desc
+	cacheHitsDesc  *prometheus.Desc
+	cacheMissDesc  *prometheus.Desc
+	cacheRateDesc  *prometheus.Desc
+	embedHitsDesc  *prometheus.Desc
+	embedMissDesc  *prometheus.Desc
+	embedRateDesc  *prometheus.Desc
+}
+
+func newSourceCollector() *sourceCollector {
+	return &sourceCollector{
+		queueDepthDesc: prometheus.NewDesc("loom_bead_queue_depth",
+			"Number of beads ready to be dispatched", []string{"project_id"}, nil),
+		cacheHitsDesc: prometheus.NewDesc("loom_cache_hits_total",
+			"Total number of cache hits", nil, nil),
+		cacheMissDesc: prometheus.NewDesc("loom_cache_misses_total",
+			"Total number of cache misses", nil, nil),
+		cacheRateDesc: prometheus.NewDesc("loom_cache_hit_ratio",
+			"Fraction of cache lookups that hit", nil, nil),
+		embedHitsDesc: prometheus.NewDesc("loom_embedding_cache_hits_total",
+			"Total number of embeddings served from the embedding cache", []string{"tier"}, nil),
+		embedMissDesc: prometheus.NewDesc("loom_embedding_cache_misses_total",
+			"Total number of embeddings computed by the embedding model", nil, nil),
+		embedRateDesc: prometheus.NewDesc("loom_embedding_cache_hit_ratio",
+			"Fraction of embeddings served from the embedding cache", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector
+func (c *sourceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.queueDepthDesc
+	ch <- c.cacheHitsDesc
+	ch <- c.cacheMissDesc
+	ch <- c.cacheRateDesc
+	ch <- c.embedHitsDesc
+	ch <- c.embedMissDesc
+	ch <- c.embedRateDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *sourceCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	queueDepth, cacheStats, embedStats := c.queueDepth, c.cacheStats, c.embedStats
+	c.mu.RUnlock()
+
+	if queueDepth != nil {
+		for projectID, n := range queueDepth() {
+			ch <- prometheus.MustNewConstMetric(c.queueDepthDesc, prometheus.GaugeValue, float64(n), projectID)
+		}
+	}
+	if cacheStats != nil {
+		stats := cacheStats()
+		ch <- prometheus.MustNewConstMetric(c.cacheHitsDesc, prometheus.CounterValue, float64(stats.Hits))
+		ch <- prometheus.MustNewConstMetric(c.cacheMissDesc, prometheus.CounterValue, float64(stats.Misses))
+		ch <- prometheus.MustNewConstMetric(c.cacheRateDesc, prometheus.GaugeValue, stats.HitRate)
+	}
+	if embedStats != nil {
+		stats := embedStats()
+		ch <- prometheus.MustNewConstMetric(c.embedHitsDesc, prometheus.CounterValue, float64(stats.MemoryHits), "memory")
+		ch <- prometheus.MustNewConstMetric(c.embedHitsDesc, prometheus.CounterValue, float64(stats.StoreHits), "database")
+		ch <- prometheus.MustNewConstMetric(c.embedMissDesc, prometheus.CounterValue, float64(stats.Misses))
+		ch <- prometheus.MustNewConstMetric(c.embedRateDesc, prometheus.GaugeValue, stats.HitRate)
+	}
+}
+
+// SetQueueDepthSource sets the function reporting the number of ready beads
+// per project
+func (m *Metrics) SetQueueDepthSource(fn func() map[string]int) {
+	m.sources.mu.Lock()
+	defer m.sources.mu.Unlock()
+	m.sources.queueDepth = fn
+}
+
+// SetCacheStatsSource sets the function reporting response cache statistics
+func (m *Metrics) SetCacheStatsSource(fn func() CacheStats) {
+	m.sources.mu.Lock()
+	defer m.sources.mu.Unlock()
+	m.sources.cacheStats = fn
+}
+
+// SetEmbeddingCacheStatsSource sets the function reporting embedding cache
+// statistics
+func (m *Metrics) SetEmbeddingCacheStatsSource(fn func() EmbeddingCacheStats) {
+	m.sources.mu.Lock()
+	defer m.sources.mu.Unlock()
+	m.sources.embedStats = fn
+}
@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// gather collects c into a private registry and returns its families by name
+func gather(t *testing.T, c prometheus.Collector) map[string]*dto.MetricFamily {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	if err := reg.Register(c); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	byName := make(map[string]*dto.MetricFamily, len(families))
+	for _, f := range families {
+		byName[f.GetName()] = f
+	}
+	return byName
+}
+
+func TestSourcesReadOnScrape(t *testing.T) {
+	c := newSourceCollector()
+	m := &Metrics{sources: c}
+	depth := map[string]int{"proj-1": 3}
+	m.SetQueueDepthSource(func() map[string]int { return depth })
+	m.SetCacheStatsSource(func() CacheStats { return CacheStats{Hits: 3, Misses: 1, HitRate: 0.75} })
+
+	families := gather(t, c)
+	if got := families["loom_bead_queue_depth"].GetMetric()[0].GetGauge().GetValue(); got != 3 {
+		t.Errorf("expected queue depth 3, got %v", got)
+	}
+	if got := families["loom_cache_hits_total"].GetMetric()[0].GetCounter().GetValue(); got != 3 {
+		t.Errorf("expected 3 cache hits, got %v", got)
+	}
+	if got := families["loom_cache_hit_ratio"].GetMetric()[0].GetGauge().GetValue(); got != 0.75 {
+		t.Errorf("expected hit ratio 0.75, got %v", got)
+	}
+
+	depth = map[string]int{"proj-1": 1}
+	if got := gather(t, c)["loom_bead_queue_depth"].GetMetric()[0].GetGauge().GetValue(); got != 1 {
+		t.Errorf("expected queue depth to be re-read as 1, got %v", got)
+	}
+}
+
+func TestEmbeddingCacheSource(t *testing.T) {
+	c := newSourceCollector()
+	m := &Metrics{sources: c}
+	m.SetEmbeddingCacheStatsSource(func() EmbeddingCacheStats {
+		return EmbeddingCacheStats{MemoryHits: 6, StoreHits: 2, Misses: 2, HitRate: 0.8}
+	})
+
+	families := gather(t, c)
+	hits := map[string]float64{}
+	for _, metric := range families["loom_embedding_cache_hits_total"].GetMetric() {
+		hits[metric.GetLabel()[0].GetValue()] = metric.GetCounter().GetValue()
+	}
+	if hits["memory"] != 6 || hits["database"] != 2 {
+		t.Errorf("expected hits by tier, got %v", hits)
+	}
+	if got := families["loom_embedding_cache_hit_ratio"].GetMetric()[0].GetGauge().GetValue(); got != 0.8 {
+		t.Errorf("expected hit ratio 0.8, got %v", got)
+	}
+}
+
+func TestSourcesWithoutProviders(t *testing.T) {
+	if families := gather(t, newSourceCollector()); len(families) != 0 {
+		t.Errorf("expected no metrics without sources, got %d families", len(families))
+	}
+}
+
+func TestRecordDispatchAndLoopTrip(t *testing.T) {
+	m := NewMetrics()
+	m.RecordDispatch("proj-2", false, 3*time.Second)
+	m.RecordLoopTrip("proj-2", "history")
+	m.RecordLoopTrip("proj-2", "history")
+
+	var out dto.Metric
+	if err := m.LoopDetectorTrips.WithLabelValues("proj-2", "history").Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if got := out.GetCounter().GetValue(); got != 2 {
+		t.Errorf("expected 2 loop trips, got %v", got)
+	}
+
+	families := gather(t, m.DispatchDuration)
+	h := families["loom_dispatch_duration_seconds"].GetMetric()[0].GetHistogram()
+	if h.GetSampleCount() != 1 || h.GetSampleSum() != 3 {
+		t.Errorf("expected one 3s dispatch, got count %d sum %v", h.GetSampleCount(), h.GetSampleSum())
+	}
+}
@@ -0,0 +1,456 @@
+// Package migrate applies versioned SQL schema migrations. Each migration is
+// a pair of files, NNNN_name.up.sql and NNNN_name.down.sql; the versions
+// applied to a database are recorded in its schema_migrations table. A
+// migration that stops part way leaves its row marked dirty, and nothing
+// more runs until an operator repairs the schema and forces the version.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// State is a migration and whether it has been applied
+type State struct {
+	Version   int64     `json:"version"`
+	Name      string    `json:"name"`
+	Applied   bool      `json:"applied"`
+	Dirty     bool      `json:"dirty"`
+	AppliedAt time.Time `json:"applied_at,omitempty"`
+}
+
+// DirtyError reports a migration that failed part way through
+type DirtyError struct {
+	Version int64
+}
+
+func (e *DirtyError) Error() string {
+	return fmt.Sprintf("database is dirty: migration %d did not finish; repair the schema by hand, then record the version it is at with 'loom migrate force <version>'", e.Version)
+}
+
+// Load reads the migrations in dir of fsys. Files are named
+// NNNN_name.up.sql and NNNN_name.down.sql; every version needs an up file,
+// the down file is optional.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		direction := path.Ext(base)
+		if direction != ".up" && direction != ".down" {
+			return nil, fmt.Errorf("migration %s: name must end in .up.sql or .down.sql", entry.Name())
+		}
+		base = strings.TrimSuffix(base, direction)
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.ParseInt(prefix, 10, 64)
+		if !ok || err != nil || version <= 0 || name == "" {
+			return nil, fmt.Errorf("migration %s: name must look like 0001_name%s.sql", entry.Name(), direction)
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, name)
+		}
+		if direction == ".up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if strings.TrimSpace(m.Up) == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Options tune a Migrator for its database
+type Options struct {
+	// Postgres numbers placeholders $1, $2... and serializes migrators
+	// on several instances with an advisory lock
+	Postgres bool
+	// LockKey is the Postgres advisory lock key
+	LockKey int64
+	// Adopt runs once, before the version table is created, so a database
+	// built before migrations were versioned can be brought to the shape the
+	// first migrations expect
+	Adopt func(ctx context.Context, conn *sql.Conn) error
+}
+
+// Migrator applies migrations to one database
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+	opts       Options
+}
+
+// New returns a Migrator for db. migrations must be sorted by version, as
+// Load returns them.
+func New(db *sql.DB, migrations []Migration, opts Options) *Migrator {
+	return &Migrator{db: db, migrations: migrations, opts: opts}
+}
+
+// Latest returns the newest known version, or 0 if there are none
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+	name      string
+	dirty     bool
+	appliedAt time.Time
+}
+
+// Status lists every known migration and any applied version this binary
+// does not know, oldest first
+func (m *Migrator) Status(ctx context.Context) ([]State, error) {
+	var states []State
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		rows, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			st := State{Version: mig.Version, Name: mig.Name}
+			if a, ok := rows[mig.Version]; ok {
+				st.Applied, st.Dirty, st.AppliedAt = true, a.dirty, a.appliedAt
+				delete(rows, mig.Version)
+			}
+			states = append(states, st)
+		}
+		for v, a := range rows {
+			states = append(states, State{Version: v, Name: a.name, Applied: true, Dirty: a.dirty, AppliedAt: a.appliedAt})
+		}
+		return nil
+	})
+	sort.Slice(states, func(i, j int) bool { return states[i].Version < states[j].Version })
+	return states, err
+}
+
+// Version returns the newest applied version and whether any migration is
+// dirty. A database with no migrations applied is at version 0.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	var version int64
+	var dirty bool
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		rows, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for v, a := range rows {
+			if v > version {
+				version = v
+			}
+			dirty = dirty || a.dirty
+		}
+		return nil
+	})
+	return version, dirty, err
+}
+
+// Up applies every pending migration and returns the ones it applied
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down reverts the newest steps applied migrations and returns the ones it
+// reverted
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		return nil, fmt.Errorf("steps must be positive")
+	}
+	var done []Migration
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		rows, err := m.checked(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := rows[mig.Version]; !ok {
+				continue
+			}
+			if err := m.down(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// To applies or reverts migrations until version is the newest applied one,
+// and returns the migrations it ran. Version 0 reverts everything.
+func (m *Migrator) To(ctx context.Context, version int64) ([]Migration, error) {
+	if version != 0 && m.find(version) < 0 {
+		return nil, fmt.Errorf("unknown migration version %d", version)
+	}
+	var done []Migration
+	err := m.withConn(ctx, func(conn *sql.Conn) error {
+		rows, err := m.checked(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := rows[mig.Version]; !ok || mig.Version <= version {
+				continue
+			}
+			if err := m.down(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		for _, mig := range m.migrations {
+			if _, ok := rows[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			if err := m.up(ctx, conn, mig); err != nil {
+				return err
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Force records version as the newest applied migration without running
+// any SQL, and clears the dirty flag. It is how an operator resumes after
+// repairing a migration that failed part way.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+	return m.withConn(ctx, func(conn *sql.Conn) error {
+		rows, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version > ?"), version); err != nil {
+			return err
+		}
+		if _, err := conn.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = ?"), false); err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := rows[mig.Version]; ok || mig.Version > version {
+				continue
+			}
+			if _, err := conn.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, ?, ?)"),
+				mig.Version, mig.Name, false, time.Now().UTC()); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// up applies one migration. Its row is written dirty first and cleaned in
+// the migration's transaction, so a process that dies part way leaves the
+// row dirty.
+func (m *Migrator) up(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	if _, err := conn.ExecContext(ctx, m.bind("INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (?, ?, ?, ?)"),
+		mig.Version, mig.Name, true, time.Now().UTC()); err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	err := m.inTx(ctx, conn, mig.Up, m.bind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), false, mig.Version)
+	if err != nil {
+		_, _ = conn.ExecContext(ctx, m.bind("DELETE FROM schema_migrations WHERE version = ?"), mig.Version)
+		return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// down reverts one migration, marking its row dirty until the revert
+// commits
+func (m *Migrator) down(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	if strings.TrimSpace(mig.Down) == "" {
+		return fmt.Errorf("migration %d_%s cannot be reverted: it has no down file", mig.Version, mig.Name)
+	}
+	if _, err := conn.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), true, mig.Version); err != nil {
+		return fmt.Errorf("revert %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	err := m.inTx(ctx, conn, mig.Down, m.bind("DELETE FROM schema_migrations WHERE version = ?"), mig.Version)
+	if err != nil {
+		_, _ = conn.ExecContext(ctx, m.bind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), false, mig.Version)
+		return fmt.Errorf("revert %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+// inTx runs script and then the bookkeeping statement in one transaction
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// withConn runs fn on one connection, holding the Postgres advisory lock,
+// after making sure the version table exists
+func (m *Migrator) withConn(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if m.opts.Postgres {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.opts.LockKey); err != nil {
+			return err
+		}
+		defer func() {
+			_, _ = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", m.opts.LockKey)
+		}()
+	}
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ensureTable creates schema_migrations, adopting a database that predates
+// it first
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	var exists bool
+	var err error
+	if m.opts.Postgres {
+		err = conn.QueryRowContext(ctx, "SELECT to_regclass('schema_migrations') IS NOT NULL").Scan(&exists)
+	} else {
+		err = conn.QueryRowContext(ctx, "SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'").Scan(&exists)
+	}
+	if err != nil || exists {
+		return err
+	}
+	if m.opts.Adopt != nil {
+		if err := m.opts.Adopt(ctx, conn); err != nil {
+			return fmt.Errorf("adopt existing schema: %w", err)
+		}
+	}
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name TEXT NOT NULL,
+			dirty BOOLEAN NOT NULL,
+			applied_at TIMESTAMP NOT NULL
+		)
+	`)
+	return err
+}
+
+// applied reads schema_migrations
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[int64]applied)
+	for rows.Next() {
+		var v int64
+		var a applied
+		if err := rows.Scan(&v, &a.name, &a.dirty, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		result[v] = a
+	}
+	return result, rows.Err()
+}
+
+// checked reads schema_migrations and refuses to go on from a dirty
+// database or one migrated by a newer binary
+func (m *Migrator) checked(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	rows, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var dirty int64
+	for v, a := range rows {
+		if a.dirty && v > dirty {
+			dirty = v
+		}
+		if v > m.Latest() {
+			return nil, fmt.Errorf("database is at migration %d, newer than this binary knows (%d)", v, m.Latest())
+		}
+	}
+	if dirty > 0 {
+		return nil, &DirtyError{Version: dirty}
+	}
+	return rows, nil
+}
+
+// find returns the index of version, or -1
+func (m *Migrator) find(version int64) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// bind rewrites ? placeholders for Postgres
+func (m *Migrator) bind(query string) string {
+	if !m.opts.Postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,208 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+var testFiles = fstest.MapFS{
+	"m/0001_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id TEXT PRIMARY KEY);")},
+	"m/0001_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+	"m/0002_gadgets.up.sql":   {Data: []byte("CREATE TABLE gadgets (id TEXT PRIMARY KEY);\nINSERT INTO gadgets VALUES ('g1');")},
+	"m/0002_gadgets.down.sql": {Data: []byte("DROP TABLE gadgets;")},
+	"m/0010_sprockets.up.sql": {Data: []byte("CREATE TABLE sprockets (id TEXT PRIMARY KEY);")},
+	"m/README.md":             {Data: []byte("not a migration")},
+}
+
+func openTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func newTestMigrator(t *testing.T, db *sql.DB, opts Options) *Migrator {
+	t.Helper()
+	migrations, err := Load(testFiles, "m")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return New(db, migrations, opts)
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n > 0
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(testFiles, "m")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(migrations) != 3 || migrations[0].Name != "widgets" || migrations[2].Version != 10 || migrations[2].Down != "" {
+		t.Fatalf("unexpected migrations %+v", migrations)
+	}
+
+	for name, file := range map[string]string{
+		"bad direction": "m/0001_a.sql",
+		"no version":    "m/first_a.up.sql",
+		"no name":       "m/0001.up.sql",
+		"down only":     "m/0001_a.down.sql",
+	} {
+		fsys := fstest.MapFS{file: {Data: []byte("SELECT 1;")}}
+		if _, err := Load(fsys, "m"); err == nil {
+			t.Errorf("%s: expected error for %s", name, file)
+		}
+	}
+}
+
+func TestUpDownTo(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, Options{})
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	if err != nil || len(applied) != 3 {
+		t.Fatalf("Up = %d, %v", len(applied), err)
+	}
+	if v, dirty, _ := m.Version(ctx); v != 10 || dirty {
+		t.Errorf("Version = %d (dirty %v), want 10", v, dirty)
+	}
+	if again, err := m.Up(ctx); err != nil || len(again) != 0 {
+		t.Errorf("second Up = %d, %v; want nothing to do", len(again), err)
+	}
+
+	// 0010 has no down file
+	if _, err := m.Down(ctx, 1); err == nil || !strings.Contains(err.Error(), "no down file") {
+		t.Errorf("expected missing down error, got %v", err)
+	}
+	if err := m.Force(ctx, 2); err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != 2 || tableExists(t, db, "gadgets") {
+		t.Fatalf("Down = %+v, %v", reverted, err)
+	}
+
+	ran, err := m.To(ctx, 2)
+	if err != nil || len(ran) != 1 || !tableExists(t, db, "gadgets") {
+		t.Fatalf("To(2) = %+v, %v", ran, err)
+	}
+	if _, err := m.To(ctx, 0); err != nil || tableExists(t, db, "widgets") {
+		t.Fatalf("To(0): %v", err)
+	}
+	if _, err := m.To(ctx, 3); err == nil {
+		t.Error("expected error for unknown version")
+	}
+
+	states, err := m.Status(ctx)
+	if err != nil || len(states) != 3 {
+		t.Fatalf("Status = %+v, %v", states, err)
+	}
+	for _, st := range states {
+		if st.Applied {
+			t.Errorf("migration %d should be pending", st.Version)
+		}
+	}
+}
+
+func TestFailedMigrationRollsBack(t *testing.T) {
+	db := openTestDB(t)
+	migrations, _ := Load(testFiles, "m")
+	migrations[1].Up = "CREATE TABLE gadgets (id TEXT PRIMARY KEY);\nINSERT INTO nowhere VALUES (1);"
+	m := New(db, migrations, Options{})
+	ctx := context.Background()
+
+	applied, err := m.Up(ctx)
+	if err == nil || len(applied) != 1 {
+		t.Fatalf("Up = %d, %v; want one applied and an error", len(applied), err)
+	}
+	if tableExists(t, db, "gadgets") {
+		t.Error("failed migration should have been rolled back")
+	}
+	if v, dirty, _ := m.Version(ctx); v != 1 || dirty {
+		t.Errorf("Version = %d (dirty %v), want 1 and clean", v, dirty)
+	}
+}
+
+func TestDirtyDatabaseRefused(t *testing.T) {
+	db := openTestDB(t)
+	m := newTestMigrator(t, db, Options{})
+	ctx := context.Background()
+	if _, err := m.To(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// A process that died inside migration 2 leaves its row dirty
+	if _, err := db.Exec("INSERT INTO schema_migrations (version, name, dirty, applied_at) VALUES (2, 'gadgets', 1, CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	var de *DirtyError
+	if _, err := m.Up(ctx); !errors.As(err, &de) || de.Version != 2 {
+		t.Fatalf("Up on dirty database: %v", err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.As(err, &de) {
+		t.Fatalf("Down on dirty database: %v", err)
+	}
+
+	if err := m.Force(ctx, 1); err != nil {
+		t.Fatalf("Force: %v", err)
+	}
+	if v, dirty, _ := m.Version(ctx); v != 1 || dirty {
+		t.Errorf("Version after force = %d (dirty %v)", v, dirty)
+	}
+	if _, err := m.Up(ctx); err != nil {
+		t.Errorf("Up after force: %v", err)
+	}
+}
+
+func TestNewerDatabaseRefused(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	if _, err := newTestMigrator(t, db, Options{}).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	migrations, _ := Load(testFiles, "m")
+	older := New(db, migrations[:2], Options{})
+	if _, err := older.Up(ctx); err == nil || !strings.Contains(err.Error(), "newer than this binary") {
+		t.Errorf("expected newer-database error, got %v", err)
+	}
+}
+
+func TestAdoptRunsOnce(t *testing.T) {
+	db := openTestDB(t)
+	if _, err := db.Exec("CREATE TABLE widgets (legacy TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	m := newTestMigrator(t, db, Options{Adopt: func(ctx context.Context, conn *sql.Conn) error {
+		calls++
+		_, err := conn.ExecContext(ctx, "DROP TABLE widgets")
+		return err
+	}})
+	ctx := context.Background()
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := m.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Errorf("Adopt called %d times, want 1", calls)
+	}
+}
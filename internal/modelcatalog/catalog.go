@@ -8,6 +8,8 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
 )
@@ -19,7 +21,11 @@ var (
 
 // Catalog holds the recommended model list.
 type Catalog struct {
-	models []internalmodels.ModelSpec
+	models       []internalmodels.ModelSpec
+	deprecations map[string]internalmodels.ModelDeprecation
+
+	metadataMu sync.RWMutex
+	metadata   map[string]map[string]internalmodels.ModelMetadata // model key -> source -> entry
 }
 
 func NewCatalog(models []internalmodels.ModelSpec) *Catalog {
@@ -35,7 +41,10 @@ func DefaultCatalog() *Catalog {
 		withParsed(internalmodels.ModelSpec{Name: "Qwen2.5-Coder-7B-Instruct", Interactivity: "fast", MinVRAMGB: 16, SuggestedGPUClass: "L40S", Rank: 5}),
 	}
 
-	return NewCatalog(defaults)
+	catalog := NewCatalog(defaults)
+	_ = catalog.AddDeprecations(DefaultDeprecations())
+	_ = catalog.AddModelMetadata(DefaultModelMetadata(), MetadataSourceDefault)
+	return catalog
 }
 
 func withParsed(spec internalmodels.ModelSpec) internalmodels.ModelSpec {
@@ -163,10 +172,14 @@ func (c *Catalog) SelectBest(available []string) (*internalmodels.ModelSpec, flo
 
 	var best *internalmodels.ModelSpec
 	bestScore := -math.MaxFloat64
+	now := time.Now()
 	for _, spec := range c.List() {
 		if _, ok := availableSet[strings.ToLower(spec.Name)]; !ok {
 			continue
 		}
+		if c.isRetired(spec.Name, now) {
+			continue
+		}
 		score := c.Score(spec)
 		if best == nil || score > bestScore {
 			copy := spec
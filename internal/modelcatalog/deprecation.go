@@ -0,0 +1,173 @@
+package modelcatalog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+const deprecationDateLayout = "2006-01-02"
+
+// DefaultDeprecationWarning is how far ahead of end-of-life a model is
+// reported as retiring.
+const DefaultDeprecationWarning = 30 * 24 * time.Hour
+
+// Deprecation statuses
+const (
+	DeprecationStatusDeprecated = "deprecated" // Past the deprecation date, still served
+	DeprecationStatusRetiring   = "retiring"   // Within the warning window of end-of-life
+	DeprecationStatusRetired    = "retired"    // Past end-of-life
+)
+
+// DefaultDeprecations is the maintained list of model end-of-life dates.
+func DefaultDeprecations() []internalmodels.ModelDeprecation {
+	return []internalmodels.ModelDeprecation{
+		{
+			Model:        "Qwen2.5-Coder-32B-Instruct",
+			DeprecatedOn: "2026-06-01",
+			EndOfLife:    "2027-03-31",
+			Replacement:  "Qwen/Qwen3-Coder-30B-A3B-Instruct",
+			Notes:        "Superseded by the Qwen3-Coder MoE release",
+		},
+		{
+			Model:        "Qwen2.5-Coder-7B-Instruct",
+			DeprecatedOn: "2026-06-01",
+			EndOfLife:    "2027-03-31",
+			Replacement:  "nvidia/NVIDIA-Nemotron-3-Nano-30B-A3B-FP8",
+			Notes:        "Replacement has similar VRAM needs with 3B active parameters",
+		},
+	}
+}
+
+// DeprecationNotice describes where a model is in its end-of-life schedule
+type DeprecationNotice struct {
+	Model         string `json:"model"`
+	Status        string `json:"status"`
+	DeprecatedOn  string `json:"deprecated_on,omitempty"`
+	EndOfLife     string `json:"end_of_life"`
+	DaysRemaining int    `json:"days_remaining"`
+	Replacement   string `json:"replacement,omitempty"`
+	Notes         string `json:"notes,omitempty"`
+}
+
+// ValidateDeprecation checks that a deprecation entry is well formed
+func ValidateDeprecation(d internalmodels.ModelDeprecation) error {
+	if d.Model == "" {
+		return fmt.Errorf("deprecation model is required")
+	}
+	eol, err := time.Parse(deprecationDateLayout, d.EndOfLife)
+	if err != nil {
+		return fmt.Errorf("model %s: invalid end_of_life %q (expected YYYY-MM-DD)", d.Model, d.EndOfLife)
+	}
+	if d.DeprecatedOn != "" {
+		dep, err := time.Parse(deprecationDateLayout, d.DeprecatedOn)
+		if err != nil {
+			return fmt.Errorf("model %s: invalid deprecated_on %q (expected YYYY-MM-DD)", d.Model, d.DeprecatedOn)
+		}
+		if dep.After(eol) {
+			return fmt.Errorf("model %s: deprecated_on is after end_of_life", d.Model)
+		}
+	}
+	if d.Replacement != "" && deprecationKey(d.Replacement) == deprecationKey(d.Model) {
+		return fmt.Errorf("model %s cannot replace itself", d.Model)
+	}
+	return nil
+}
+
+// AddDeprecations adds or replaces deprecation entries. Entries are keyed
+// by model name without the vendor prefix, case-insensitively.
+func (c *Catalog) AddDeprecations(entries []internalmodels.ModelDeprecation) error {
+	if c == nil {
+		return fmt.Errorf("catalog is nil")
+	}
+	for _, d := range entries {
+		if err := ValidateDeprecation(d); err != nil {
+			return err
+		}
+	}
+	if c.deprecations == nil {
+		c.deprecations = make(map[string]internalmodels.ModelDeprecation)
+	}
+	for _, d := range entries {
+		c.deprecations[deprecationKey(d.Model)] = d
+	}
+	return nil
+}
+
+// Deprecations returns all known deprecation entries sorted by end-of-life
+func (c *Catalog) Deprecations() []internalmodels.ModelDeprecation {
+	if c == nil {
+		return nil
+	}
+	out := make([]internalmodels.ModelDeprecation, 0, len(c.deprecations))
+	for _, d := range c.deprecations {
+		out = append(out, d)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].EndOfLife != out[j].EndOfLife {
+			return out[i].EndOfLife < out[j].EndOfLife
+		}
+		return out[i].Model < out[j].Model
+	})
+	return out
+}
+
+// CheckDeprecation returns a notice for model if it is deprecated, within
+// warnWithin of end-of-life, or already retired. It returns nil for models
+// with no schedule or whose schedule has not started.
+func (c *Catalog) CheckDeprecation(model string, now time.Time, warnWithin time.Duration) *DeprecationNotice {
+	if c == nil || model == "" {
+		return nil
+	}
+	d, ok := c.deprecations[deprecationKey(model)]
+	if !ok {
+		return nil
+	}
+	eol, err := time.Parse(deprecationDateLayout, d.EndOfLife)
+	if err != nil {
+		return nil
+	}
+
+	notice := &DeprecationNotice{
+		Model:         model,
+		DeprecatedOn:  d.DeprecatedOn,
+		EndOfLife:     d.EndOfLife,
+		DaysRemaining: int(eol.Sub(now).Hours() / 24),
+		Replacement:   d.Replacement,
+		Notes:         d.Notes,
+	}
+	switch {
+	case !now.Before(eol):
+		notice.Status = DeprecationStatusRetired
+		notice.DaysRemaining = 0
+	case eol.Sub(now) <= warnWithin:
+		notice.Status = DeprecationStatusRetiring
+	case d.DeprecatedOn != "":
+		dep, err := time.Parse(deprecationDateLayout, d.DeprecatedOn)
+		if err != nil || now.Before(dep) {
+			return nil
+		}
+		notice.Status = DeprecationStatusDeprecated
+	default:
+		return nil
+	}
+	return notice
+}
+
+// isRetired reports whether a model is past its end-of-life date
+func (c *Catalog) isRetired(model string, now time.Time) bool {
+	notice := c.CheckDeprecation(model, now, 0)
+	return notice != nil && notice.Status == DeprecationStatusRetired
+}
+
+// deprecationKey normalizes a model name so "Qwen/Qwen2.5-Coder-7B-Instruct"
+// and "qwen2.5-coder-7b-instruct" refer to the same entry
+func deprecationKey(model string) string {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return strings.ToLower(strings.TrimSpace(model))
+}
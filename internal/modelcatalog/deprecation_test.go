@@ -0,0 +1,132 @@
+package modelcatalog
+
+import (
+	"testing"
+	"time"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+func mustDate(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(deprecationDateLayout, s)
+	if err != nil {
+		t.Fatalf("bad date %q: %v", s, err)
+	}
+	return d
+}
+
+func TestCheckDeprecation(t *testing.T) {
+	c := NewCatalog(nil)
+	if err := c.AddDeprecations([]internalmodels.ModelDeprecation{{
+		Model:        "Old-Coder-7B",
+		DeprecatedOn: "2026-01-01",
+		EndOfLife:    "2026-06-01",
+		Replacement:  "New-Coder-8B",
+	}}); err != nil {
+		t.Fatalf("AddDeprecations failed: %v", err)
+	}
+	warn := 30 * 24 * time.Hour
+
+	tests := []struct {
+		name   string
+		model  string
+		now    string
+		status string
+	}{
+		{"before deprecation", "Old-Coder-7B", "2025-12-01", ""},
+		{"deprecated", "Old-Coder-7B", "2026-02-01", DeprecationStatusDeprecated},
+		{"vendor prefix and case", "vendor/old-coder-7b", "2026-02-01", DeprecationStatusDeprecated},
+		{"retiring", "Old-Coder-7B", "2026-05-15", DeprecationStatusRetiring},
+		{"retired", "Old-Coder-7B", "2026-06-01", DeprecationStatusRetired},
+		{"unknown model", "Other-7B", "2026-06-01", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notice := c.CheckDeprecation(tt.model, mustDate(t, tt.now), warn)
+			if tt.status == "" {
+				if notice != nil {
+					t.Errorf("expected no notice, got %+v", notice)
+				}
+				return
+			}
+			if notice == nil || notice.Status != tt.status {
+				t.Fatalf("expected status %q, got %+v", tt.status, notice)
+			}
+			if notice.Replacement != "New-Coder-8B" {
+				t.Errorf("replacement = %q", notice.Replacement)
+			}
+		})
+	}
+}
+
+func TestAddDeprecations_Validation(t *testing.T) {
+	c := NewCatalog(nil)
+	invalid := []internalmodels.ModelDeprecation{
+		{EndOfLife: "2026-01-01"},
+		{Model: "m", EndOfLife: "Jan 1"},
+		{Model: "m", DeprecatedOn: "2026-02-01", EndOfLife: "2026-01-01"},
+		{Model: "vendor/m", EndOfLife: "2026-01-01", Replacement: "m"},
+	}
+	for i, d := range invalid {
+		if err := c.AddDeprecations([]internalmodels.ModelDeprecation{d}); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+	if len(c.Deprecations()) != 0 {
+		t.Error("invalid entries should not be added")
+	}
+}
+
+func TestSelectBest_SkipsRetired(t *testing.T) {
+	c := NewCatalog([]internalmodels.ModelSpec{
+		{Name: "Best-1B", Interactivity: "fast", Rank: 1},
+		{Name: "Fallback-70B", Interactivity: "slow", Rank: 2},
+	})
+	if err := c.AddDeprecations([]internalmodels.ModelDeprecation{{Model: "Best-1B", EndOfLife: "2000-01-01"}}); err != nil {
+		t.Fatalf("AddDeprecations failed: %v", err)
+	}
+	best, _, ok := c.SelectBest([]string{"Best-1B", "Fallback-70B"})
+	if !ok || best.Name != "Fallback-70B" {
+		t.Errorf("expected retired model to be skipped, got %+v", best)
+	}
+}
+
+func TestMigrationReportSummarize(t *testing.T) {
+	report := &MigrationReport{Samples: []MigrationSample{
+		{
+			Baseline:  MigrationRun{Response: `{"action":"read_file","path":"a.go"}`, PromptTokens: 100, CompletionTokens: 20, CostUSD: 0.002, LatencyMs: 800, ValidJSON: true},
+			Candidate: MigrationRun{Response: `{"action":"read_file","path":"a.go"}`, PromptTokens: 100, CompletionTokens: 10, CostUSD: 0.001, LatencyMs: 400, ValidJSON: true},
+		},
+		{
+			Baseline:  MigrationRun{Response: "fix the bug in main", PromptTokens: 50, CompletionTokens: 10, CostUSD: 0.001, LatencyMs: 600},
+			Candidate: MigrationRun{Response: "fix the bug in main quickly", PromptTokens: 50, CompletionTokens: 10, CostUSD: 0.001, LatencyMs: 600},
+		},
+	}}
+	report.Summarize()
+
+	if report.Compared != 2 || report.CandidateSuccessRate != 1 {
+		t.Errorf("unexpected counts: %+v", report)
+	}
+	if report.CostDeltaPct != -33.33 {
+		t.Errorf("CostDeltaPct = %v, want -33.33", report.CostDeltaPct)
+	}
+	if report.AvgLatencyDeltaMs != -200 {
+		t.Errorf("AvgLatencyDeltaMs = %v, want -200", report.AvgLatencyDeltaMs)
+	}
+	if report.Recommendation != MigrationRecommendMigrate {
+		t.Errorf("Recommendation = %q, want migrate (similarity %v)", report.Recommendation, report.AvgSimilarity)
+	}
+
+	report.Samples[1].Candidate = MigrationRun{Error: "timeout"}
+	report.Summarize()
+	if report.Recommendation != MigrationRecommendReview {
+		t.Errorf("expected review when candidate fails, got %q", report.Recommendation)
+	}
+
+	empty := &MigrationReport{}
+	empty.Summarize()
+	if empty.Recommendation != MigrationInsufficientData {
+		t.Errorf("expected insufficient_data, got %q", empty.Recommendation)
+	}
+}
@@ -0,0 +1,172 @@
+package modelcatalog
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+// Metadata sources in increasing precedence. A higher-precedence source
+// overrides the fields it sets; unset fields fall through to lower ones.
+const (
+	MetadataSourceDefault   = "default"
+	MetadataSourceDiscovery = "discovery"
+	MetadataSourceConfig    = "config"
+)
+
+var metadataPrecedence = []string{MetadataSourceDefault, MetadataSourceDiscovery, MetadataSourceConfig}
+
+// DefaultModelMetadata is the built-in metadata for the default catalog.
+// Self-hosted models have no per-token price.
+func DefaultModelMetadata() []internalmodels.ModelMetadata {
+	tools := true
+	return []internalmodels.ModelMetadata{
+		{Model: "Qwen/Qwen3-Coder-480B-A35B-Instruct", ContextWindow: 262144, SupportsTools: &tools},
+		{Model: "Qwen/Qwen3-Coder-30B-A3B-Instruct", ContextWindow: 262144, SupportsTools: &tools},
+		{Model: "Qwen2.5-Coder-32B-Instruct", ContextWindow: 32768, SupportsTools: &tools},
+		{Model: "Qwen2.5-Coder-7B-Instruct", ContextWindow: 32768, SupportsTools: &tools},
+	}
+}
+
+// ValidateModelMetadata checks that a metadata entry is well formed
+func ValidateModelMetadata(m internalmodels.ModelMetadata) error {
+	if m.Model == "" {
+		return fmt.Errorf("metadata model is required")
+	}
+	if m.ContextWindow < 0 || m.MaxOutputTokens < 0 {
+		return fmt.Errorf("model %s: token limits must not be negative", m.Model)
+	}
+	if m.ContextWindow > 0 && m.MaxOutputTokens > m.ContextWindow {
+		return fmt.Errorf("model %s: max_output_tokens exceeds context_window", m.Model)
+	}
+	if m.InputCostPerMToken < 0 || m.OutputCostPerMToken < 0 {
+		return fmt.Errorf("model %s: pricing must not be negative", m.Model)
+	}
+	if m.KnowledgeCutoff != "" {
+		if _, err := time.Parse("2006-01", m.KnowledgeCutoff); err != nil {
+			return fmt.Errorf("model %s: invalid knowledge_cutoff %q (expected YYYY-MM)", m.Model, m.KnowledgeCutoff)
+		}
+	}
+	return nil
+}
+
+// AddModelMetadata adds or replaces metadata entries from one source.
+// Entries are keyed like deprecations: vendor prefix stripped, case-insensitive.
+func (c *Catalog) AddModelMetadata(entries []internalmodels.ModelMetadata, source string) error {
+	if c == nil {
+		return fmt.Errorf("catalog is nil")
+	}
+	for _, m := range entries {
+		if err := ValidateModelMetadata(m); err != nil {
+			return err
+		}
+	}
+
+	c.metadataMu.Lock()
+	defer c.metadataMu.Unlock()
+	if c.metadata == nil {
+		c.metadata = make(map[string]map[string]internalmodels.ModelMetadata)
+	}
+	for _, m := range entries {
+		key := deprecationKey(m.Model)
+		if c.metadata[key] == nil {
+			c.metadata[key] = make(map[string]internalmodels.ModelMetadata)
+		}
+		m.Source = source
+		c.metadata[key][source] = m
+	}
+	return nil
+}
+
+// RecordDiscoveredModel stores what provider discovery learned about a
+// model. Only the context window is reported by OpenAI-compatible servers.
+func (c *Catalog) RecordDiscoveredModel(model string, contextWindow int) {
+	if c == nil || model == "" || contextWindow <= 0 {
+		return
+	}
+	_ = c.AddModelMetadata([]internalmodels.ModelMetadata{{Model: model, ContextWindow: contextWindow}}, MetadataSourceDiscovery)
+}
+
+// ModelMetadata returns the merged metadata for a model. Source names the
+// highest-precedence source that contributed.
+func (c *Catalog) ModelMetadata(model string) (internalmodels.ModelMetadata, bool) {
+	if c == nil || model == "" {
+		return internalmodels.ModelMetadata{}, false
+	}
+	c.metadataMu.RLock()
+	defer c.metadataMu.RUnlock()
+
+	layers, ok := c.metadata[deprecationKey(model)]
+	if !ok {
+		return internalmodels.ModelMetadata{}, false
+	}
+	merged := internalmodels.ModelMetadata{Model: model}
+	for _, source := range metadataPrecedence {
+		if m, ok := layers[source]; ok {
+			mergeMetadata(&merged, m)
+		}
+	}
+	return merged, true
+}
+
+// ListModelMetadata returns merged metadata for every known model, sorted by name
+func (c *Catalog) ListModelMetadata() []internalmodels.ModelMetadata {
+	if c == nil {
+		return nil
+	}
+	c.metadataMu.RLock()
+	names := make([]string, 0, len(c.metadata))
+	for _, layers := range c.metadata {
+		// Report the name as written by the most authoritative source
+		for i := len(metadataPrecedence) - 1; i >= 0; i-- {
+			if m, ok := layers[metadataPrecedence[i]]; ok {
+				names = append(names, m.Model)
+				break
+			}
+		}
+	}
+	c.metadataMu.RUnlock()
+
+	sort.Strings(names)
+	out := make([]internalmodels.ModelMetadata, 0, len(names))
+	for _, name := range names {
+		if m, ok := c.ModelMetadata(name); ok {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// mergeMetadata overlays the fields set in over onto dst
+func mergeMetadata(dst *internalmodels.ModelMetadata, over internalmodels.ModelMetadata) {
+	if over.ContextWindow > 0 {
+		dst.ContextWindow = over.ContextWindow
+	}
+	if over.MaxOutputTokens > 0 {
+		dst.MaxOutputTokens = over.MaxOutputTokens
+	}
+	if over.SupportsTools != nil {
+		dst.SupportsTools = over.SupportsTools
+	}
+	if over.SupportsVision != nil {
+		dst.SupportsVision = over.SupportsVision
+	}
+	if over.SupportsJSONMode != nil {
+		dst.SupportsJSONMode = over.SupportsJSONMode
+	}
+	if over.SupportsJSONSchema != nil {
+		dst.SupportsJSONSchema = over.SupportsJSONSchema
+	}
+	if over.InputCostPerMToken > 0 {
+		dst.InputCostPerMToken = over.InputCostPerMToken
+	}
+	if over.OutputCostPerMToken > 0 {
+		dst.OutputCostPerMToken = over.OutputCostPerMToken
+	}
+	if over.KnowledgeCutoff != "" {
+		dst.KnowledgeCutoff = over.KnowledgeCutoff
+	}
+	dst.Source = over.Source
+}
@@ -0,0 +1,72 @@
+package modelcatalog
+
+import (
+	"testing"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+func TestModelMetadataPrecedence(t *testing.T) {
+	c := NewCatalog(nil)
+	yes, no := true, false
+	if err := c.AddModelMetadata([]internalmodels.ModelMetadata{
+		{Model: "vendor/Coder-7B", ContextWindow: 8192, SupportsTools: &yes, InputCostPerMToken: 1},
+	}, MetadataSourceDefault); err != nil {
+		t.Fatalf("AddModelMetadata failed: %v", err)
+	}
+	c.RecordDiscoveredModel("coder-7b", 32768)
+	if err := c.AddModelMetadata([]internalmodels.ModelMetadata{
+		{Model: "Coder-7B", SupportsTools: &no, OutputCostPerMToken: 3},
+	}, MetadataSourceConfig); err != nil {
+		t.Fatalf("AddModelMetadata failed: %v", err)
+	}
+
+	md, ok := c.ModelMetadata("Coder-7B")
+	if !ok {
+		t.Fatal("expected metadata")
+	}
+	if md.ContextWindow != 32768 {
+		t.Errorf("ContextWindow = %d, want discovered 32768", md.ContextWindow)
+	}
+	if md.SupportsTools == nil || *md.SupportsTools {
+		t.Errorf("config should override supports_tools, got %v", md.SupportsTools)
+	}
+	if md.InputCostPerMToken != 1 || md.OutputCostPerMToken != 3 {
+		t.Errorf("pricing should merge across sources, got %v/%v", md.InputCostPerMToken, md.OutputCostPerMToken)
+	}
+	if md.Source != MetadataSourceConfig {
+		t.Errorf("Source = %q, want config", md.Source)
+	}
+	if cost := md.Cost(1_000_000, 500_000); cost != 2.5 {
+		t.Errorf("Cost = %v, want 2.5", cost)
+	}
+
+	if list := c.ListModelMetadata(); len(list) != 1 || list[0].Model != "Coder-7B" {
+		t.Errorf("ListModelMetadata = %+v", list)
+	}
+}
+
+func TestValidateModelMetadata(t *testing.T) {
+	invalid := []internalmodels.ModelMetadata{
+		{ContextWindow: 10},
+		{Model: "m", ContextWindow: -1},
+		{Model: "m", ContextWindow: 100, MaxOutputTokens: 200},
+		{Model: "m", InputCostPerMToken: -1},
+		{Model: "m", KnowledgeCutoff: "2024"},
+	}
+	for i, m := range invalid {
+		if err := ValidateModelMetadata(m); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+	if err := ValidateModelMetadata(internalmodels.ModelMetadata{Model: "m", KnowledgeCutoff: "2024-06"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDefaultCatalogHasMetadata(t *testing.T) {
+	md, ok := DefaultCatalog().ModelMetadata("Qwen/Qwen2.5-Coder-32B-Instruct")
+	if !ok || md.ContextWindow == 0 {
+		t.Errorf("expected default metadata, got %+v", md)
+	}
+}
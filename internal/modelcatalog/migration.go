@@ -0,0 +1,181 @@
+package modelcatalog
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+)
+
+// Migration recommendations
+const (
+	MigrationRecommendMigrate = "migrate"
+	MigrationRecommendReview  = "review"
+	MigrationInsufficientData = "insufficient_data"
+)
+
+// minMigrationSimilarity is the average response similarity below which a
+// migration is flagged for human review
+const minMigrationSimilarity = 0.5
+
+// MigrationRun is one model's answer to a sampled prompt
+type MigrationRun struct {
+	Model            string  `json:"model"`
+	Response         string  `json:"response,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	LatencyMs        int64   `json:"latency_ms"`
+	CostUSD          float64 `json:"cost_usd"`
+	ValidJSON        bool    `json:"valid_json"`
+	Error            string  `json:"error,omitempty"`
+}
+
+// Succeeded reports whether the run produced a response
+func (r MigrationRun) Succeeded() bool {
+	return r.Error == "" && strings.TrimSpace(r.Response) != ""
+}
+
+// MigrationSample pairs the current and replacement model on one prompt
+type MigrationSample struct {
+	Source     string       `json:"source"` // Request log ID or "provided"
+	Prompt     string       `json:"prompt"`
+	Baseline   MigrationRun `json:"baseline"`
+	Candidate  MigrationRun `json:"candidate"`
+	Similarity float64      `json:"similarity"`
+}
+
+// MigrationReport summarizes quality and cost deltas between a model and
+// its replacement over a sample of recent prompts
+type MigrationReport struct {
+	ProviderID       string             `json:"provider_id"`
+	CurrentModel     string             `json:"current_model"`
+	ReplacementModel string             `json:"replacement_model"`
+	CandidateTarget  string             `json:"candidate_provider_id"`
+	Deprecation      *DeprecationNotice `json:"deprecation,omitempty"`
+	Samples          []MigrationSample  `json:"samples"`
+
+	Compared             int       `json:"compared"`
+	BaselineSuccessRate  float64   `json:"baseline_success_rate"`
+	CandidateSuccessRate float64   `json:"candidate_success_rate"`
+	AvgSimilarity        float64   `json:"avg_similarity"`
+	JSONValidityDelta    float64   `json:"json_validity_delta"` // Candidate minus baseline share of valid JSON responses
+	AvgLatencyDeltaMs    float64   `json:"avg_latency_delta_ms"`
+	TokenDeltaPct        float64   `json:"token_delta_pct"`
+	CostDeltaUSD         float64   `json:"cost_delta_usd"`
+	CostDeltaPct         float64   `json:"cost_delta_pct"`
+	Recommendation       string    `json:"recommendation"`
+	GeneratedAt          time.Time `json:"generated_at"`
+}
+
+// Summarize computes the report's aggregate deltas and recommendation from
+// its samples
+func (r *MigrationReport) Summarize() {
+	r.GeneratedAt = time.Now()
+	r.Compared = len(r.Samples)
+	if r.Compared == 0 {
+		r.Recommendation = MigrationInsufficientData
+		return
+	}
+
+	var baseOK, candOK, baseJSON, candJSON, both int
+	var simSum, latencyDelta, baseCost, candCost float64
+	var baseTokens, candTokens int
+	for i := range r.Samples {
+		s := &r.Samples[i]
+		if s.Baseline.Succeeded() {
+			baseOK++
+		}
+		if s.Candidate.Succeeded() {
+			candOK++
+		}
+		if s.Baseline.ValidJSON {
+			baseJSON++
+		}
+		if s.Candidate.ValidJSON {
+			candJSON++
+		}
+		if s.Baseline.Succeeded() && s.Candidate.Succeeded() {
+			s.Similarity = ResponseSimilarity(s.Baseline.Response, s.Candidate.Response)
+			simSum += s.Similarity
+			latencyDelta += float64(s.Candidate.LatencyMs - s.Baseline.LatencyMs)
+			both++
+		}
+		baseCost += s.Baseline.CostUSD
+		candCost += s.Candidate.CostUSD
+		baseTokens += s.Baseline.PromptTokens + s.Baseline.CompletionTokens
+		candTokens += s.Candidate.PromptTokens + s.Candidate.CompletionTokens
+	}
+
+	n := float64(r.Compared)
+	r.BaselineSuccessRate = round2(float64(baseOK) / n)
+	r.CandidateSuccessRate = round2(float64(candOK) / n)
+	r.JSONValidityDelta = round2(float64(candJSON-baseJSON) / n)
+	r.CostDeltaUSD = math.Round((candCost-baseCost)*1e6) / 1e6
+	r.CostDeltaPct = percentDelta(baseCost, candCost)
+	r.TokenDeltaPct = percentDelta(float64(baseTokens), float64(candTokens))
+	if both > 0 {
+		r.AvgSimilarity = round2(simSum / float64(both))
+		r.AvgLatencyDeltaMs = round2(latencyDelta / float64(both))
+	}
+
+	switch {
+	case both == 0:
+		r.Recommendation = MigrationInsufficientData
+	case r.CandidateSuccessRate >= r.BaselineSuccessRate &&
+		r.JSONValidityDelta >= 0 &&
+		r.AvgSimilarity >= minMigrationSimilarity:
+		r.Recommendation = MigrationRecommendMigrate
+	default:
+		r.Recommendation = MigrationRecommendReview
+	}
+}
+
+// ResponseSimilarity is the Jaccard similarity of the two responses' word
+// sets. It is a cheap proxy for "did the replacement say the same thing".
+func ResponseSimilarity(a, b string) float64 {
+	setA := wordSet(a)
+	setB := wordSet(b)
+	if len(setA) == 0 && len(setB) == 0 {
+		return 1
+	}
+	inter := 0
+	for w := range setA {
+		if _, ok := setB[w]; ok {
+			inter++
+		}
+	}
+	union := len(setA) + len(setB) - inter
+	return round2(float64(inter) / float64(union))
+}
+
+// IsJSONResponse reports whether a response is a JSON object, which is what
+// agents are expected to emit for actions
+func IsJSONResponse(response string) bool {
+	trimmed := strings.TrimSpace(response)
+	trimmed = strings.TrimPrefix(trimmed, "```json")
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	trimmed = strings.TrimSuffix(trimmed, "```")
+	var v map[string]interface{}
+	return json.Unmarshal([]byte(strings.TrimSpace(trimmed)), &v) == nil
+}
+
+func wordSet(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_')
+	}) {
+		set[w] = struct{}{}
+	}
+	return set
+}
+
+func percentDelta(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return round2((after - before) / before * 100)
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
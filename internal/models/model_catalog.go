@@ -15,3 +15,53 @@ type ModelSpec struct {
 	SuggestedGPUClass    string   `json:"suggested_gpu_class" yaml:"suggested_gpu_class"`
 	Rank                 int      `json:"rank" yaml:"rank"`
 }
+
+// ModelDeprecation records a model's end-of-life schedule. Dates are
+// YYYY-MM-DD so the catalog can be maintained by hand in config files.
+type ModelDeprecation struct {
+	Model        string `json:"model" yaml:"model"`
+	DeprecatedOn string `json:"deprecated_on,omitempty" yaml:"deprecated_on"`
+	EndOfLife    string `json:"end_of_life" yaml:"end_of_life"`
+	Replacement  string `json:"replacement,omitempty" yaml:"replacement"`
+	Notes        string `json:"notes,omitempty" yaml:"notes"`
+}
+
+// ModelMetadata describes a model's limits, capabilities and pricing.
+// Capability flags are pointers so "unknown" is distinct from "unsupported";
+// zero limits and prices mean unknown.
+type ModelMetadata struct {
+	Model               string  `json:"model" yaml:"model"`
+	ContextWindow       int     `json:"context_window,omitempty" yaml:"context_window"`
+	MaxOutputTokens     int     `json:"max_output_tokens,omitempty" yaml:"max_output_tokens"`
+	SupportsTools       *bool   `json:"supports_tools,omitempty" yaml:"supports_tools"`
+	SupportsVision      *bool   `json:"supports_vision,omitempty" yaml:"supports_vision"`
+	SupportsJSONMode    *bool   `json:"supports_json_mode,omitempty" yaml:"supports_json_mode"`
+	SupportsJSONSchema  *bool   `json:"supports_json_schema,omitempty" yaml:"supports_json_schema"`
+	InputCostPerMToken  float64 `json:"input_cost_per_mtoken,omitempty" yaml:"input_cost_per_mtoken"`
+	OutputCostPerMToken float64 `json:"output_cost_per_mtoken,omitempty" yaml:"output_cost_per_mtoken"`
+	KnowledgeCutoff     string  `json:"knowledge_cutoff,omitempty" yaml:"knowledge_cutoff"` // YYYY-MM
+	Source              string  `json:"source,omitempty" yaml:"-"`                          // default, discovery, or config
+}
+
+// HasPricing reports whether per-token prices are known
+func (m ModelMetadata) HasPricing() bool {
+	return m.InputCostPerMToken > 0 || m.OutputCostPerMToken > 0
+}
+
+// Cost returns the price in USD of a request with the given token counts
+func (m ModelMetadata) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*m.InputCostPerMToken + float64(completionTokens)*m.OutputCostPerMToken) / 1e6
+}
+
+// BlendedCostPerMToken averages input and output prices for callers that
+// only track total tokens
+func (m ModelMetadata) BlendedCostPerMToken() float64 {
+	switch {
+	case m.InputCostPerMToken > 0 && m.OutputCostPerMToken > 0:
+		return (m.InputCostPerMToken + m.OutputCostPerMToken) / 2
+	case m.InputCostPerMToken > 0:
+		return m.InputCostPerMToken
+	default:
+		return m.OutputCostPerMToken
+	}
+}
@@ -0,0 +1,218 @@
+package notifications
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Deliverer sends notifications over an out-of-app channel such as email
+// or webhooks. In-app notifications are stored and streamed by the Manager.
+type Deliverer interface {
+	Deliver(notification *Notification) error
+}
+
+// RegisterDeliverer registers the deliverer used for a channel
+func (m *Manager) RegisterDeliverer(channel string, d Deliverer) error {
+	if channel == ChannelInApp {
+		return fmt.Errorf("in-app notifications are delivered by the manager")
+	}
+	if !isValidChannel(channel) {
+		return fmt.Errorf("invalid channel %q", channel)
+	}
+
+	m.deliverersMu.Lock()
+	defer m.deliverersMu.Unlock()
+	if m.deliverers == nil {
+		m.deliverers = make(map[string]Deliverer)
+	}
+	m.deliverers[channel] = d
+	return nil
+}
+
+// deliver hands a notification to its channel
+func (m *Manager) deliver(channel string, notification *Notification) error {
+	if channel == ChannelInApp {
+		if err := m.trackEscalation(notification); err != nil {
+			log.Printf("Failed to track escalation of notification %s: %v", notification.ID, err)
+		}
+		if err := m.CreateNotification(notification); err != nil {
+			return err
+		}
+		// Broadcast to user's SSE streams
+		m.broadcastToUser(notification.UserID, notification)
+		return nil
+	}
+
+	m.deliverersMu.RLock()
+	d := m.deliverers[channel]
+	m.deliverersMu.RUnlock()
+	if d == nil {
+		// No backend configured for this channel
+		return nil
+	}
+	return d.Deliver(notification)
+}
+
+// Notify sends a copy of n to its user on every channel their preferences
+// enable, outside that channel's quiet hours. Unlike activity
+// notifications it is not filtered by subscribed events or priority: the
+// sender chose its recipient.
+func (m *Manager) Notify(n *Notification) error {
+	prefs, err := m.GetPreferences(n.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get preferences for user %s: %w", n.UserID, err)
+	}
+	if n.Priority == "" {
+		n.Priority = PriorityNormal
+	}
+	var errs []error
+	for _, channel := range Channels {
+		channelPrefs := prefs.ForChannel(channel)
+		if !channelPrefs.Enabled || m.inQuietHours(prefs, channelPrefs, n.Priority) {
+			continue
+		}
+		notification := *n
+		notification.ID = uuid.New().String()
+		notification.Status = StatusUnread
+		notification.CreatedAt = time.Now()
+		if channel != ChannelInApp {
+			notification.Metadata = map[string]interface{}{}
+			for k, v := range n.Metadata {
+				notification.Metadata[k] = v
+			}
+			notification.Metadata["channel"] = channel
+		}
+		if err := m.deliver(channel, &notification); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", channel, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// channelEnabled returns the top-level enable flag for a channel
+func (p *NotificationPreferences) channelEnabled(channel string) bool {
+	switch channel {
+	case ChannelInApp:
+		return p.EnableInApp
+	case ChannelEmail:
+		return p.EnableEmail
+	case ChannelWebhook:
+		return p.EnableWebhook
+	case ChannelPush:
+		return p.EnablePush
+	default:
+		return false
+	}
+}
+
+// ForChannel resolves the effective settings for a channel, applying the
+// channel's overrides on top of the top-level preferences
+func (p *NotificationPreferences) ForChannel(channel string) ChannelPreferences {
+	effective := ChannelPreferences{
+		Enabled:          p.channelEnabled(channel),
+		MinPriority:      p.MinPriority,
+		SubscribedEvents: p.SubscribedEvents,
+		QuietHoursStart:  p.QuietHoursStart,
+		QuietHoursEnd:    p.QuietHoursEnd,
+		QuietSchedule:    p.quietSchedule(),
+	}
+
+	override, ok := p.Channels[channel]
+	if !ok || override == nil {
+		return effective
+	}
+
+	effective.Enabled = override.Enabled
+	if override.MinPriority != "" {
+		effective.MinPriority = override.MinPriority
+	}
+	if len(override.SubscribedEvents) > 0 {
+		effective.SubscribedEvents = override.SubscribedEvents
+	}
+	if override.QuietHoursStart != "" && override.QuietHoursEnd != "" {
+		effective.QuietHoursStart = override.QuietHoursStart
+		effective.QuietHoursEnd = override.QuietHoursEnd
+	}
+	// A channel with its own quiet hours or schedule replaces every
+	// top-level window
+	if (override.QuietHoursStart != "" && override.QuietHoursEnd != "") || len(override.QuietSchedule) > 0 {
+		effective.QuietSchedule = quietWindows(override.QuietHoursStart, override.QuietHoursEnd, override.QuietSchedule)
+	}
+	return effective
+}
+
+// Validate checks channel names, priorities, quiet hour formats and
+// schedules, the time zone and mute rules
+func (p *NotificationPreferences) Validate() error {
+	if err := validatePriority(p.MinPriority); err != nil {
+		return err
+	}
+	if err := validateQuietHours(p.QuietHoursStart, p.QuietHoursEnd); err != nil {
+		return err
+	}
+	if err := validateQuietSchedule(p.QuietSchedule); err != nil {
+		return err
+	}
+	if p.Timezone != "" {
+		if _, err := time.LoadLocation(p.Timezone); err != nil {
+			return fmt.Errorf("invalid time zone %q", p.Timezone)
+		}
+	}
+
+	for channel, cp := range p.Channels {
+		if !isValidChannel(channel) {
+			return fmt.Errorf("invalid channel %q", channel)
+		}
+		if cp == nil {
+			continue
+		}
+		if err := validatePriority(cp.MinPriority); err != nil {
+			return fmt.Errorf("channel %s: %w", channel, err)
+		}
+		if err := validateQuietHours(cp.QuietHoursStart, cp.QuietHoursEnd); err != nil {
+			return fmt.Errorf("channel %s: %w", channel, err)
+		}
+		if err := validateQuietSchedule(cp.QuietSchedule); err != nil {
+			return fmt.Errorf("channel %s: %w", channel, err)
+		}
+	}
+
+	for _, rule := range p.MuteRules {
+		if rule == nil {
+			continue
+		}
+		if err := rule.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePriority(priority string) error {
+	switch priority {
+	case "", PriorityLow, PriorityNormal, PriorityHigh, PriorityCritical:
+		return nil
+	default:
+		return fmt.Errorf("invalid priority %q", priority)
+	}
+}
+
+func validateQuietHours(start, end string) error {
+	if start == "" && end == "" {
+		return nil
+	}
+	if start == "" || end == "" {
+		return fmt.Errorf("quiet hours require both start and end")
+	}
+	if _, err := time.Parse("15:04", start); err != nil {
+		return fmt.Errorf("invalid quiet hours start %q (expected HH:MM)", start)
+	}
+	if _, err := time.Parse("15:04", end); err != nil {
+		return fmt.Errorf("invalid quiet hours end %q (expected HH:MM)", end)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+)
+
+type recordingDeliverer struct {
+	delivered []*Notification
+}
+
+func (r *recordingDeliverer) Deliver(n *Notification) error {
+	r.delivered = append(r.delivered, n)
+	return nil
+}
+
+func TestForChannel_InheritsAndOverrides(t *testing.T) {
+	prefs := &NotificationPreferences{
+		EnableInApp:      true,
+		EnableEmail:      false,
+		MinPriority:      PriorityNormal,
+		SubscribedEvents: []string{"bead.assigned"},
+		Channels: map[string]*ChannelPreferences{
+			ChannelEmail: {Enabled: true, MinPriority: PriorityCritical},
+		},
+	}
+
+	inApp := prefs.ForChannel(ChannelInApp)
+	if !inApp.Enabled || inApp.MinPriority != PriorityNormal {
+		t.Errorf("in-app should inherit top-level settings, got %+v", inApp)
+	}
+
+	email := prefs.ForChannel(ChannelEmail)
+	if !email.Enabled || email.MinPriority != PriorityCritical {
+		t.Errorf("email override not applied, got %+v", email)
+	}
+	if len(email.SubscribedEvents) != 1 || email.SubscribedEvents[0] != "bead.assigned" {
+		t.Errorf("email should inherit subscribed events, got %v", email.SubscribedEvents)
+	}
+
+	if prefs.ForChannel(ChannelWebhook).Enabled {
+		t.Error("webhook should be disabled")
+	}
+}
+
+func TestPreferencesValidate(t *testing.T) {
+	valid := &NotificationPreferences{
+		MinPriority: PriorityNormal,
+		Channels: map[string]*ChannelPreferences{
+			ChannelEmail: {Enabled: true, QuietHoursStart: "22:00", QuietHoursEnd: "07:00"},
+		},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := []*NotificationPreferences{
+		{Channels: map[string]*ChannelPreferences{"sms": {Enabled: true}}},
+		{Channels: map[string]*ChannelPreferences{ChannelEmail: {MinPriority: "urgent"}}},
+		{Channels: map[string]*ChannelPreferences{ChannelEmail: {QuietHoursStart: "22:00"}}},
+		{QuietHoursStart: "25:00", QuietHoursEnd: "07:00"},
+	}
+	for i, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestProcessActivity_PerChannel(t *testing.T) {
+	m := newTestManager(t)
+	email := &recordingDeliverer{}
+	if err := m.RegisterDeliverer(ChannelEmail, email); err != nil {
+		t.Fatalf("RegisterDeliverer failed: %v", err)
+	}
+
+	prefs, err := m.GetPreferences("user-admin")
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	prefs.Channels = map[string]*ChannelPreferences{
+		ChannelEmail: {Enabled: true, MinPriority: PriorityCritical},
+	}
+	if err := m.UpdatePreferences(prefs); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+
+	// High priority: in-app only
+	assigned := &activity.Activity{
+		EventType:     "bead.assigned",
+		ResourceID:    "bead-1",
+		ResourceTitle: "Fix login",
+		Metadata:      map[string]interface{}{"assigned_to": "user-admin"},
+	}
+	if err := m.ProcessActivity(assigned); err != nil {
+		t.Fatalf("ProcessActivity failed: %v", err)
+	}
+	if len(email.delivered) != 0 {
+		t.Errorf("expected no email for high priority, got %d", len(email.delivered))
+	}
+
+	// Critical priority: in-app and email
+	failed := &activity.Activity{
+		EventType:     "workflow.failed",
+		Action:        "failed",
+		ResourceType:  "workflow",
+		ResourceID:    "wf-1",
+		ResourceTitle: "nightly",
+	}
+	if err := m.ProcessActivity(failed); err != nil {
+		t.Fatalf("ProcessActivity failed: %v", err)
+	}
+	if len(email.delivered) != 1 {
+		t.Fatalf("expected one email, got %d", len(email.delivered))
+	}
+	if email.delivered[0].Metadata["channel"] != ChannelEmail {
+		t.Errorf("expected channel metadata, got %v", email.delivered[0].Metadata)
+	}
+
+	stored, err := m.GetNotifications("user-admin", "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetNotifications failed: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Errorf("expected 2 in-app notifications, got %d", len(stored))
+	}
+}
@@ -0,0 +1,88 @@
+package notifications
+
+import (
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+// UnreadCounts is how many of a user's notifications are unread, in all,
+// by priority and by project
+type UnreadCounts struct {
+	Total      int            `json:"total"`
+	ByPriority map[string]int `json:"by_priority"`
+	ByProject  map[string]int `json:"by_project"` // Notifications of no project count under ""
+}
+
+// CountDelta is a change to how many of a user's notifications of one
+// project and priority are unread. Streams send them so badges stay current
+// without asking for the counts again.
+type CountDelta struct {
+	ProjectID string `json:"project_id"`
+	Priority  string `json:"priority"`
+	Delta     int    `json:"delta"`
+}
+
+// UnreadCounts returns a user's unread counts. They are read from counters
+// kept as notifications are created, read, snoozed and archived, so the
+// cost does not grow with the number of notifications.
+func (m *Manager) UnreadCounts(userID string) (*UnreadCounts, error) {
+	rows, err := m.db.GetUnreadCounts(userID)
+	if err != nil {
+		return nil, err
+	}
+	counts := &UnreadCounts{ByPriority: make(map[string]int), ByProject: make(map[string]int)}
+	for _, c := range rows {
+		counts.Total += c.Count
+		counts.ByPriority[c.Priority] += c.Count
+		counts.ByProject[c.ProjectID] += c.Count
+	}
+	return counts, nil
+}
+
+// SubscribeCounts creates a stream of a user's unread count changes for a
+// subscriber. Unsubscribe closes it.
+func (m *Manager) SubscribeCounts(userID, subscriberID string) chan []CountDelta {
+	m.subscribersMu.Lock()
+	defer m.subscribersMu.Unlock()
+
+	if m.countSubscribers == nil {
+		m.countSubscribers = make(map[string]map[string]chan []CountDelta)
+	}
+	if m.countSubscribers[userID] == nil {
+		m.countSubscribers[userID] = make(map[string]chan []CountDelta)
+	}
+	ch := make(chan []CountDelta, 100)
+	m.countSubscribers[userID][subscriberID] = ch
+	return ch
+}
+
+// publishCounts sends count changes to the subscribers of the users they
+// belong to. A subscriber too far behind to take them would drift from the
+// true counts, so its stream is closed instead; clients fetch the counts
+// again when they reconnect.
+func (m *Manager) publishCounts(changes []database.UnreadCount) {
+	if len(changes) == 0 {
+		return
+	}
+	byUser := make(map[string][]CountDelta)
+	for _, c := range changes {
+		byUser[c.UserID] = append(byUser[c.UserID], CountDelta{ProjectID: c.ProjectID, Priority: c.Priority, Delta: c.Count})
+	}
+
+	type subscription struct{ userID, subscriberID string }
+	var behind []subscription
+	m.subscribersMu.RLock()
+	for userID, deltas := range byUser {
+		for subscriberID, ch := range m.countSubscribers[userID] {
+			select {
+			case ch <- deltas:
+			default:
+				behind = append(behind, subscription{userID, subscriberID})
+			}
+		}
+	}
+	m.subscribersMu.RUnlock()
+
+	for _, sub := range behind {
+		m.Unsubscribe(sub.userID, sub.subscriberID)
+	}
+}
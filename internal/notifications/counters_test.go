@@ -0,0 +1,79 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+func TestUnreadCountsAndDeltas(t *testing.T) {
+	m := newTestManager(t)
+	deltas := m.SubscribeCounts("user-admin", "test")
+	defer m.Unsubscribe("user-admin", "test")
+
+	assigned := &activity.Activity{
+		EventType:     "bead.assigned",
+		ProjectID:     "proj-1",
+		ResourceType:  "bead",
+		ResourceID:    "bead-1",
+		ResourceTitle: "Fix login",
+		Metadata:      map[string]interface{}{"assigned_to": "user-admin"},
+	}
+	if err := m.ProcessActivity(assigned); err != nil {
+		t.Fatalf("ProcessActivity failed: %v", err)
+	}
+	select {
+	case got := <-deltas:
+		if len(got) != 1 || got[0] != (CountDelta{ProjectID: "proj-1", Priority: PriorityHigh, Delta: 1}) {
+			t.Errorf("unexpected delta on create: %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a count delta on create")
+	}
+
+	counts, err := m.UnreadCounts("user-admin")
+	if err != nil {
+		t.Fatalf("UnreadCounts failed: %v", err)
+	}
+	if counts.Total != 1 || counts.ByPriority[PriorityHigh] != 1 || counts.ByProject["proj-1"] != 1 {
+		t.Errorf("unexpected counts: %+v", counts)
+	}
+
+	stored, _ := m.GetNotifications("user-admin", StatusUnread, 10, 0)
+	if len(stored) != 1 || stored[0].ProjectID != "proj-1" {
+		t.Fatalf("expected the notification of proj-1, got %+v", stored)
+	}
+	if err := m.Archive(stored[0].ID, "user-admin"); err != nil {
+		t.Fatalf("Archive failed: %v", err)
+	}
+	select {
+	case got := <-deltas:
+		if len(got) != 1 || got[0].Delta != -1 {
+			t.Errorf("unexpected delta on archive: %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected a count delta on archive")
+	}
+	if counts, _ := m.UnreadCounts("user-admin"); counts.Total != 0 {
+		t.Errorf("expected no unread notifications, got %+v", counts)
+	}
+}
+
+func TestPublishCounts_ClosesLaggingStream(t *testing.T) {
+	m := newTestManager(t)
+	deltas := m.SubscribeCounts("user-admin", "slow")
+	defer m.Unsubscribe("user-admin", "slow")
+
+	change := []database.UnreadCount{{UserID: "user-admin", ProjectID: "proj-1", Priority: PriorityHigh, Count: 1}}
+	for i := 0; i <= cap(deltas); i++ {
+		m.publishCounts(change)
+	}
+	for i := 0; i < cap(deltas); i++ {
+		<-deltas
+	}
+	if _, ok := <-deltas; ok {
+		t.Fatal("expected the stream of a subscriber that fell behind to be closed")
+	}
+}
@@ -0,0 +1,390 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// Escalation statuses
+const (
+	EscalationPending      = "pending"
+	EscalationAcknowledged = "acknowledged"
+	EscalationCEO          = "ceo" // The on-call chain was exhausted and a CEO decision was created
+)
+
+// defaultAckWindow is how long a notification waits for acknowledgment
+// when neither the configuration nor the project's chain sets it
+const defaultAckWindow = 30 * time.Minute
+
+// escalationCheckInterval is how often due escalations are advanced
+var escalationCheckInterval = time.Minute
+
+// OnCallChain is the order a project's unacknowledged high and critical
+// notifications are escalated in
+type OnCallChain struct {
+	ProjectID        string    `json:"project_id"`
+	UserIDs          []string  `json:"user_ids"`
+	AckWindowMinutes int       `json:"ack_window_minutes,omitempty"` // 0 uses the configured default
+	UpdatedBy        string    `json:"updated_by,omitempty"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// Escalation tracks a high or critical notification until someone
+// acknowledges it. Notifications about the same activity share one.
+type Escalation struct {
+	ID             string     `json:"id"`
+	ProjectID      string     `json:"project_id"`
+	EventType      string     `json:"event_type"`
+	Title          string     `json:"title"`
+	Message        string     `json:"message"`
+	Link           string     `json:"link,omitempty"`
+	Priority       string     `json:"priority"`
+	Status         string     `json:"status"`
+	Notified       []string   `json:"notified"` // Users paged so far, in order
+	Step           int        `json:"step"`     // Members of the on-call chain paged so far
+	EscalateAt     *time.Time `json:"escalate_at,omitempty"`
+	AcknowledgedBy string     `json:"acknowledged_by,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledged_at,omitempty"`
+	DecisionID     string     `json:"decision_id,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// CEOEscalator puts an escalation no one in the on-call chain acknowledged
+// to the CEO, returning the ID of the decision it created
+type CEOEscalator func(e *Escalation) (string, error)
+
+// SetEscalation configures escalation of unacknowledged notifications: the
+// default acknowledgment window and where exhausted chains go. A negative
+// window turns escalation off.
+func (m *Manager) SetEscalation(ackWindow time.Duration, ceo CEOEscalator) {
+	m.escalationMu.Lock()
+	defer m.escalationMu.Unlock()
+	if ackWindow == 0 {
+		ackWindow = defaultAckWindow
+	}
+	m.ackWindow = ackWindow
+	m.ceoEscalator = ceo
+}
+
+// SetProjectOrgLookup sets how the organization of a project is found, so
+// on-call chains hold only users of the project's organization
+func (m *Manager) SetProjectOrgLookup(lookup func(projectID string) string) {
+	m.escalationMu.Lock()
+	defer m.escalationMu.Unlock()
+	m.projectOrg = lookup
+}
+
+// projectOrgOf returns the organization of a project, the default one
+// when there is no lookup
+func (m *Manager) projectOrgOf(projectID string) string {
+	m.escalationMu.RLock()
+	lookup := m.projectOrg
+	m.escalationMu.RUnlock()
+	if lookup == nil {
+		return org.DefaultID
+	}
+	return org.Normalize(lookup(projectID))
+}
+
+func (m *Manager) escalationSettings() (time.Duration, CEOEscalator) {
+	m.escalationMu.RLock()
+	defer m.escalationMu.RUnlock()
+	return m.ackWindow, m.ceoEscalator
+}
+
+// needsAcknowledgment reports whether a notification is tracked for
+// escalation: high and critical notifications about a project
+func needsAcknowledgment(n *Notification) bool {
+	return n.ProjectID != "" && (n.Priority == PriorityHigh || n.Priority == PriorityCritical)
+}
+
+// trackEscalation starts tracking an in-app notification that needs
+// acknowledgment, when its project has an on-call chain, and records the
+// escalation on the notification. Notifications sent by an escalation
+// already carry it.
+func (m *Manager) trackEscalation(n *Notification) error {
+	if !needsAcknowledgment(n) {
+		return nil
+	}
+	if _, ok := n.Metadata["escalation_id"]; ok {
+		return nil
+	}
+	window, _ := m.escalationSettings()
+	if window <= 0 {
+		return nil
+	}
+	chain, err := m.db.GetOnCallChain(n.ProjectID)
+	if err != nil || chain == nil || len(chain.UserIDs) == 0 {
+		return err
+	}
+	if chain.AckWindow > 0 {
+		window = chain.AckWindow
+	}
+
+	sourceKey := n.ActivityID
+	if sourceKey == "" {
+		sourceKey = "notification:" + n.ID
+	}
+	escalateAt := n.CreatedAt.Add(window)
+	e, err := m.db.OpenNotificationEscalation(&database.NotificationEscalation{
+		ID:         uuid.New().String(),
+		SourceKey:  sourceKey,
+		ProjectID:  n.ProjectID,
+		EventType:  n.EventType,
+		Title:      n.Title,
+		Message:    n.Message,
+		Link:       n.Link,
+		Priority:   n.Priority,
+		EscalateAt: &escalateAt,
+		CreatedAt:  n.CreatedAt,
+	}, n.UserID)
+	if err != nil {
+		return err
+	}
+	if n.Metadata == nil {
+		n.Metadata = map[string]interface{}{}
+	}
+	n.Metadata["escalation_id"] = e.ID
+	return nil
+}
+
+// Acknowledge acknowledges one of a user's notifications, stopping the
+// escalation it belongs to. Acknowledging a notification that is not
+// escalated is an error.
+func (m *Manager) Acknowledge(notificationID, userID string) (*Escalation, error) {
+	n, err := m.db.GetNotification(notificationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	notification := fromDBNotifications([]*database.Notification{n})[0]
+	escalationID, _ := notification.Metadata["escalation_id"].(string)
+	if escalationID == "" {
+		return nil, fmt.Errorf("notification %s does not need acknowledgment", notificationID)
+	}
+	if _, err := m.db.AcknowledgeNotificationEscalation(escalationID, userID, time.Now()); err != nil {
+		return nil, err
+	}
+	if notification.Status == StatusUnread {
+		if err := m.MarkRead(notificationID); err != nil {
+			return nil, err
+		}
+	}
+	e, err := m.db.GetNotificationEscalation(escalationID)
+	if err != nil {
+		return nil, err
+	}
+	return fromDBEscalation(e), nil
+}
+
+// Escalations lists escalations, newest first, by status and in any of
+// projectIDs; no projects means every project
+func (m *Manager) Escalations(projectIDs []string, status string, limit int) ([]*Escalation, error) {
+	rows, err := m.db.ListNotificationEscalations(database.NotificationEscalationFilters{ProjectIDs: projectIDs, Status: status, Limit: limit})
+	if err != nil {
+		return nil, err
+	}
+	escalations := make([]*Escalation, 0, len(rows))
+	for _, e := range rows {
+		escalations = append(escalations, fromDBEscalation(e))
+	}
+	return escalations, nil
+}
+
+// EscalateDue advances every escalation whose acknowledgment window ended
+// at or before now, returning how many advanced. Each goes to the next
+// member of its project's on-call chain not yet paged or, when there is
+// none, to the CEO.
+func (m *Manager) EscalateDue(now time.Time) (int, error) {
+	if window, _ := m.escalationSettings(); window <= 0 {
+		return 0, nil
+	}
+	due, err := m.db.ListNotificationEscalations(database.NotificationEscalationFilters{DueBy: &now})
+	if err != nil {
+		return 0, err
+	}
+	advanced := 0
+	for _, e := range due {
+		claimed, err := m.escalate(e, now)
+		if err != nil {
+			log.Printf("Failed to escalate notification escalation %s: %v", e.ID, err)
+			continue
+		}
+		if claimed {
+			advanced++
+		}
+	}
+	return advanced, nil
+}
+
+// escalate pages the next person in an escalation's on-call chain. The
+// step is claimed before anyone is paged, so an escalation acknowledged
+// meanwhile pages no one and it reports false.
+func (m *Manager) escalate(e *database.NotificationEscalation, now time.Time) (bool, error) {
+	window, ceo := m.escalationSettings()
+	chain, err := m.db.GetOnCallChain(e.ProjectID)
+	if err != nil {
+		return false, err
+	}
+	var next string
+	if chain != nil {
+		if chain.AckWindow > 0 {
+			window = chain.AckWindow
+		}
+		notified := make(map[string]bool, len(e.Notified))
+		for _, id := range e.Notified {
+			notified[id] = true
+		}
+		for ; e.Step < len(chain.UserIDs); e.Step++ {
+			if id := chain.UserIDs[e.Step]; !notified[id] {
+				next = id
+				e.Step++
+				break
+			}
+		}
+	}
+
+	if next == "" {
+		e.Status = EscalationCEO
+		e.EscalateAt = nil
+		if claimed, err := m.db.UpdateNotificationEscalation(e); err != nil || !claimed {
+			return false, err
+		}
+		if ceo == nil {
+			return true, nil
+		}
+		decisionID, err := ceo(fromDBEscalation(e))
+		if err != nil {
+			return true, err
+		}
+		e.DecisionID = decisionID
+		return true, m.db.SetNotificationEscalationDecision(e.ID, decisionID)
+	}
+
+	escalateAt := now.Add(window)
+	e.Notified = append(e.Notified, next)
+	e.EscalateAt = &escalateAt
+	if claimed, err := m.db.UpdateNotificationEscalation(e); err != nil || !claimed {
+		return false, err
+	}
+	return true, m.Notify(&Notification{
+		UserID:    next,
+		ProjectID: e.ProjectID,
+		EventType: e.EventType,
+		Title:     "Escalated: " + e.Title,
+		Message:   fmt.Sprintf("%s\n\nNot acknowledged by %d people before you. Acknowledge it to stop the escalation.", e.Message, len(e.Notified)-1),
+		Link:      e.Link,
+		Priority:  e.Priority,
+		Metadata:  map[string]interface{}{"escalation_id": e.ID, "escalation_step": e.Step},
+	})
+}
+
+// escalateLoop advances due escalations every escalationCheckInterval
+// until ctx is done
+func (m *Manager) escalateLoop(ctx context.Context) {
+	ticker := time.NewTicker(escalationCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := m.EscalateDue(now); err != nil {
+				log.Printf("Failed to escalate notifications: %v", err)
+			}
+		}
+	}
+}
+
+// OnCallChain returns a project's on-call chain, or nil when it has none
+func (m *Manager) OnCallChain(projectID string) (*OnCallChain, error) {
+	chain, err := m.db.GetOnCallChain(projectID)
+	if err != nil || chain == nil {
+		return nil, err
+	}
+	return &OnCallChain{
+		ProjectID:        chain.ProjectID,
+		UserIDs:          chain.UserIDs,
+		AckWindowMinutes: int(chain.AckWindow / time.Minute),
+		UpdatedBy:        chain.UpdatedBy,
+		UpdatedAt:        chain.UpdatedAt,
+	}, nil
+}
+
+// SetOnCallChain replaces a project's on-call chain
+func (m *Manager) SetOnCallChain(chain *OnCallChain) error {
+	if chain.ProjectID == "" {
+		return fmt.Errorf("project_id is required")
+	}
+	if len(chain.UserIDs) == 0 {
+		return fmt.Errorf("an on-call chain needs at least one user")
+	}
+	if chain.AckWindowMinutes < 0 {
+		return fmt.Errorf("ack_window_minutes must not be negative")
+	}
+	seen := make(map[string]bool, len(chain.UserIDs))
+	for _, id := range chain.UserIDs {
+		if id == "" || seen[id] {
+			return fmt.Errorf("on-call chain users must be distinct and non-empty")
+		}
+		seen[id] = true
+	}
+	users, err := m.db.ListUsers()
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+	userOrgs := make(map[string]string, len(users))
+	for _, u := range users {
+		userOrgs[u.ID] = org.Normalize(u.OrgID)
+	}
+	orgID := m.projectOrgOf(chain.ProjectID)
+	for _, id := range chain.UserIDs {
+		userOrg, ok := userOrgs[id]
+		if !ok {
+			return fmt.Errorf("user not found: %s", id)
+		}
+		if userOrg != orgID {
+			return fmt.Errorf("user %s is not in the project's organization", id)
+		}
+	}
+	chain.UpdatedAt = time.Now()
+	return m.db.SaveOnCallChain(&database.OnCallChain{
+		ProjectID: chain.ProjectID,
+		UserIDs:   chain.UserIDs,
+		AckWindow: time.Duration(chain.AckWindowMinutes) * time.Minute,
+		UpdatedBy: chain.UpdatedBy,
+		UpdatedAt: chain.UpdatedAt,
+	})
+}
+
+// RemoveOnCallChain stops escalating a project's notifications
+func (m *Manager) RemoveOnCallChain(projectID string) error {
+	return m.db.DeleteOnCallChain(projectID)
+}
+
+func fromDBEscalation(e *database.NotificationEscalation) *Escalation {
+	return &Escalation{
+		ID:             e.ID,
+		ProjectID:      e.ProjectID,
+		EventType:      e.EventType,
+		Title:          e.Title,
+		Message:        e.Message,
+		Link:           e.Link,
+		Priority:       e.Priority,
+		Status:         e.Status,
+		Notified:       e.Notified,
+		Step:           e.Step,
+		EscalateAt:     e.EscalateAt,
+		AcknowledgedBy: e.AcknowledgedBy,
+		AcknowledgedAt: e.AcknowledgedAt,
+		DecisionID:     e.DecisionID,
+		CreatedAt:      e.CreatedAt,
+		UpdatedAt:      e.UpdatedAt,
+	}
+}
@@ -0,0 +1,152 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+func TestEscalation_ChainThenCEO(t *testing.T) {
+	m := newTestManager(t)
+	for _, id := range []string{"oncall-1", "oncall-2"} {
+		if err := m.db.CreateUser(id, id, id+"@example.com", "user"); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+	var ceo []*Escalation
+	m.SetEscalation(10*time.Minute, func(e *Escalation) (string, error) {
+		ceo = append(ceo, e)
+		return "bd-dec-1", nil
+	})
+	if err := m.SetOnCallChain(&OnCallChain{ProjectID: "proj-1", UserIDs: []string{"user-admin", "oncall-1", "oncall-2"}}); err != nil {
+		t.Fatalf("SetOnCallChain failed: %v", err)
+	}
+
+	// Normal priority and projectless notifications are not tracked
+	if err := m.Notify(&Notification{UserID: "user-admin", ProjectID: "proj-1", EventType: "bead.created", Title: "FYI", Priority: PriorityNormal}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	if err := m.Notify(&Notification{UserID: "user-admin", ProjectID: "proj-1", EventType: "workflow.failed", Title: "Nightly failed", Link: "/workflows/wf-1", Priority: PriorityCritical}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	pending, _ := m.Escalations([]string{"proj-1"}, EscalationPending, 0)
+	if len(pending) != 1 || pending[0].Notified[0] != "user-admin" {
+		t.Fatalf("expected one pending escalation for user-admin, got %+v", pending)
+	}
+
+	// Not yet due
+	start := pending[0].CreatedAt
+	if n, _ := m.EscalateDue(start.Add(9 * time.Minute)); n != 0 {
+		t.Fatalf("expected nothing due, got %d", n)
+	}
+
+	// user-admin already has it, so oncall-1 is next
+	if n, err := m.EscalateDue(start.Add(11 * time.Minute)); err != nil || n != 1 {
+		t.Fatalf("EscalateDue = %d, %v", n, err)
+	}
+	paged, _ := m.GetNotifications("oncall-1", StatusUnread, 10, 0)
+	if len(paged) != 1 || paged[0].Title != "Escalated: Nightly failed" || paged[0].Link != "/workflows/wf-1" {
+		t.Fatalf("expected oncall-1 to be paged, got %+v", paged)
+	}
+
+	m.EscalateDue(start.Add(22 * time.Minute))
+	m.EscalateDue(start.Add(33 * time.Minute))
+	if len(ceo) != 1 {
+		t.Fatalf("expected the exhausted chain to go to the CEO, got %d", len(ceo))
+	}
+	escalations, _ := m.Escalations([]string{"proj-1"}, "", 0)
+	if e := escalations[0]; e.Status != EscalationCEO || e.DecisionID != "bd-dec-1" || len(e.Notified) != 3 {
+		t.Errorf("unexpected escalation after the CEO: %+v", e)
+	}
+}
+
+func TestEscalation_AcknowledgeStops(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.db.CreateUser("oncall-1", "oncall-1", "oncall-1@example.com", "user"); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	m.SetEscalation(time.Minute, nil)
+	if err := m.SetOnCallChain(&OnCallChain{ProjectID: "proj-1", UserIDs: []string{"oncall-1"}}); err != nil {
+		t.Fatalf("SetOnCallChain failed: %v", err)
+	}
+	if err := m.Notify(&Notification{UserID: "user-admin", ProjectID: "proj-1", EventType: "bead.assigned", Title: "Fix it", Priority: PriorityHigh}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+	notifs, _ := m.GetNotifications("user-admin", StatusUnread, 10, 0)
+	if len(notifs) != 1 {
+		t.Fatalf("expected one notification, got %d", len(notifs))
+	}
+
+	e, err := m.Acknowledge(notifs[0].ID, "user-admin")
+	if err != nil {
+		t.Fatalf("Acknowledge failed: %v", err)
+	}
+	if e.Status != EscalationAcknowledged || e.AcknowledgedBy != "user-admin" {
+		t.Errorf("unexpected escalation: %+v", e)
+	}
+	if n, _ := m.EscalateDue(time.Now().Add(time.Hour)); n != 0 {
+		t.Errorf("expected an acknowledged escalation to stay put, got %d advanced", n)
+	}
+	if _, err := m.Acknowledge(notifs[0].ID, "oncall-1"); err == nil {
+		t.Error("expected another user's notification not to be acknowledged")
+	}
+
+	if err := m.SetOnCallChain(&OnCallChain{ProjectID: "proj-1", UserIDs: []string{"a", "a"}}); err == nil {
+		t.Error("expected duplicate chain members to be rejected")
+	}
+	if err := m.SetOnCallChain(&OnCallChain{ProjectID: "proj-1", UserIDs: []string{"nobody"}}); err == nil {
+		t.Error("expected an unknown chain member to be rejected")
+	}
+	m.SetProjectOrgLookup(func(projectID string) string {
+		if projectID == "proj-acme" {
+			return "acme"
+		}
+		return ""
+	})
+	if err := m.SetOnCallChain(&OnCallChain{ProjectID: "proj-acme", UserIDs: []string{"oncall-1"}}); err == nil {
+		t.Error("expected a member of another organization to be rejected")
+	}
+}
+
+func TestEscalation_AcknowledgedWhileDuePagesNoOne(t *testing.T) {
+	m := newTestManager(t)
+	if err := m.db.CreateUser("oncall-1", "oncall-1", "oncall-1@example.com", "user"); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	var ceo int
+	m.SetEscalation(time.Minute, func(e *Escalation) (string, error) {
+		ceo++
+		return "bd-dec-1", nil
+	})
+	if err := m.SetOnCallChain(&OnCallChain{ProjectID: "proj-1", UserIDs: []string{"oncall-1"}}); err != nil {
+		t.Fatalf("SetOnCallChain failed: %v", err)
+	}
+	if err := m.Notify(&Notification{UserID: "user-admin", ProjectID: "proj-1", EventType: "bead.assigned", Title: "Fix it", Priority: PriorityHigh}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	// The poller reads the due escalation, then it is acknowledged
+	due := time.Now().Add(time.Hour)
+	stale, err := m.db.ListNotificationEscalations(database.NotificationEscalationFilters{DueBy: &due})
+	if err != nil || len(stale) != 1 {
+		t.Fatalf("ListNotificationEscalations = %d, %v", len(stale), err)
+	}
+	notifs, _ := m.GetNotifications("user-admin", StatusUnread, 10, 0)
+	if _, err := m.Acknowledge(notifs[0].ID, "user-admin"); err != nil {
+		t.Fatalf("Acknowledge failed: %v", err)
+	}
+
+	if claimed, err := m.escalate(stale[0], due); err != nil || claimed {
+		t.Fatalf("escalate = %v, %v, want unclaimed", claimed, err)
+	}
+	if paged, _ := m.GetNotifications("oncall-1", StatusUnread, 10, 0); len(paged) != 0 {
+		t.Errorf("expected no one to be paged, got %+v", paged)
+	}
+
+	// Nor does an exhausted chain reach the CEO
+	stale[0].Step = 1
+	if claimed, _ := m.escalate(stale[0], due); claimed || ceo != 0 {
+		t.Errorf("escalate = %v with %d CEO decisions, want none", claimed, ceo)
+	}
+}
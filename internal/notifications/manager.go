@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -10,14 +11,22 @@ import (
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/activity"
 	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
 )
 
 // Manager handles notification logic
 type Manager struct {
-	db            *database.Database
-	activityMgr   *activity.Manager
-	subscribers   map[string]map[string]chan *Notification // userID -> subscriberID -> channel
-	subscribersMu sync.RWMutex
+	db               *database.Database
+	activityMgr      *activity.Manager
+	subscribers      map[string]map[string]chan *Notification // userID -> subscriberID -> channel
+	countSubscribers map[string]map[string]chan []CountDelta  // userID -> subscriberID -> channel
+	subscribersMu    sync.RWMutex
+	deliverers       map[string]Deliverer // channel -> out-of-app deliverer
+	deliverersMu     sync.RWMutex
+	ackWindow        time.Duration // Default wait for acknowledgment before escalating; negative disables escalation
+	ceoEscalator     CEOEscalator
+	projectOrg       func(projectID string) string
+	escalationMu     sync.RWMutex
 }
 
 // NewManager creates a new notification manager
@@ -26,6 +35,7 @@ func NewManager(db *database.Database, activityMgr *activity.Manager) *Manager {
 		db:          db,
 		activityMgr: activityMgr,
 		subscribers: make(map[string]map[string]chan *Notification),
+		ackWindow:   defaultAckWindow,
 	}
 
 	// Subscribe to activity manager
@@ -34,6 +44,16 @@ func NewManager(db *database.Database, activityMgr *activity.Manager) *Manager {
 	return m
 }
 
+// Start runs the background work, waking snoozed notifications and
+// advancing escalations, until ctx is done. Configure escalation first.
+func (m *Manager) Start(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	go m.wakeSnoozedLoop(ctx)
+	go m.escalateLoop(ctx)
+}
+
 // subscribeToActivities subscribes to activity feed
 func (m *Manager) subscribeToActivities() {
 	activityChan := m.activityMgr.Subscribe("notification-manager")
@@ -45,7 +65,11 @@ func (m *Manager) subscribeToActivities() {
 	}
 }
 
-// ProcessActivity processes an activity and creates notifications
+// ProcessActivity processes an activity and creates notifications.
+// Only users of the activity's organization are notified. Each delivery
+// channel is evaluated independently against the user's per-channel
+// preferences. Users subscribed to a saved view matching the activity are
+// notified of it even when the default rules would pass them over.
 func (m *Manager) ProcessActivity(activity *activity.Activity) error {
 	// Get all users from database
 	users, err := m.db.ListUsers()
@@ -53,10 +77,13 @@ func (m *Manager) ProcessActivity(activity *activity.Activity) error {
 		return fmt.Errorf("failed to list users: %w", err)
 	}
 
+	orgID := org.Normalize(activity.OrgID)
+	subscribed, err := m.subscribedUsers(activity, orgID)
+	if err != nil {
+		log.Printf("Failed to match activity views: %v", err)
+	}
 	for _, user := range users {
-		// Check if user should be notified
-		shouldNotify, notification := m.ShouldNotify(activity, user.ID)
-		if !shouldNotify {
+		if org.Normalize(user.OrgID) != orgID {
 			continue
 		}
 
@@ -67,25 +94,22 @@ func (m *Manager) ProcessActivity(activity *activity.Activity) error {
 			continue
 		}
 
-		// Check if in-app notifications are enabled
-		if !prefs.EnableInApp {
-			continue
-		}
+		for _, channel := range Channels {
+			shouldNotify, notification := m.shouldNotifyChannel(activity, user.ID, prefs, channel, subscribed[user.ID])
+			if !shouldNotify {
+				continue
+			}
 
-		// Create notification
-		if err := m.CreateNotification(notification); err != nil {
-			log.Printf("Failed to create notification for user %s: %v", user.ID, err)
-			continue
+			if err := m.deliver(channel, notification); err != nil {
+				log.Printf("Failed to deliver %s notification for user %s: %v", channel, user.ID, err)
+			}
 		}
-
-		// Broadcast to user's SSE streams
-		m.broadcastToUser(user.ID, notification)
 	}
 
 	return nil
 }
 
-// ShouldNotify determines if a user should be notified about an activity
+// ShouldNotify determines if a user should be notified in-app about an activity
 func (m *Manager) ShouldNotify(activity *activity.Activity, userID string) (bool, *Notification) {
 	// Get user preferences
 	prefs, err := m.GetPreferences(userID)
@@ -93,26 +117,46 @@ func (m *Manager) ShouldNotify(activity *activity.Activity, userID string) (bool
 		return false, nil
 	}
 
-	// Check if event type is subscribed
-	if !m.isEventSubscribed(activity.EventType, prefs.SubscribedEvents) {
+	return m.shouldNotifyChannel(activity, userID, prefs, ChannelInApp, false)
+}
+
+// shouldNotifyChannel determines if a user should be notified about an
+// activity on a specific delivery channel. A user subscribed to a view of
+// the activity chose it themselves, so neither their subscribed events nor
+// the rules of who is told of what apply. Their mute rules always do.
+func (m *Manager) shouldNotifyChannel(activity *activity.Activity, userID string, prefs *NotificationPreferences, channel string, viewed bool) (bool, *Notification) {
+	channelPrefs := prefs.ForChannel(channel)
+	if !channelPrefs.Enabled {
 		return false, nil
 	}
 
-	// Check quiet hours
-	if m.inQuietHours(prefs) {
+	if prefs.Muted(activity, time.Now()) {
+		return false, nil
+	}
+
+	// Check if event type is subscribed
+	if !viewed && !m.isEventSubscribed(activity.EventType, channelPrefs.SubscribedEvents) {
 		return false, nil
 	}
 
 	// Apply notification rules
 	priority := m.determinePriority(activity)
 
+	// Check quiet hours
+	if m.inQuietHours(prefs, channelPrefs, priority) {
+		return false, nil
+	}
+
 	// Check priority threshold
-	if !m.meetsPriorityThreshold(priority, prefs.MinPriority) {
+	if !m.meetsPriorityThreshold(priority, channelPrefs.MinPriority) {
 		return false, nil
 	}
 
 	// Apply specific rules
-	title, message, link := m.formatNotification(activity, userID)
+	title, message, link := m.formatNotificationForChannel(activity, userID, channel)
+	if title == "" && viewed {
+		title, message, link = m.renderViewNotification(activity, userID, channel)
+	}
 	if title == "" {
 		return false, nil
 	}
@@ -121,6 +165,7 @@ func (m *Manager) ShouldNotify(activity *activity.Activity, userID string) (bool
 		ID:         uuid.New().String(),
 		UserID:     userID,
 		ActivityID: activity.ID,
+		ProjectID:  activity.ProjectID,
 		EventType:  activity.EventType,
 		Title:      title,
 		Message:    message,
@@ -129,53 +174,45 @@ func (m *Manager) ShouldNotify(activity *activity.Activity, userID string) (bool
 		Priority:   priority,
 		CreatedAt:  time.Now(),
 	}
+	if channel != ChannelInApp {
+		notification.Metadata = map[string]interface{}{"channel": channel}
+	}
 
 	return true, notification
 }
 
-// formatNotification formats a notification based on activity and user
+// formatNotification formats a notification based on activity and user.
+// The rules below decide whether the user is a recipient; the text itself
+// comes from the org's notification templates.
 func (m *Manager) formatNotification(activity *activity.Activity, userID string) (title, message, link string) {
-	// Check for direct assignment
-	if activity.EventType == "bead.assigned" {
-		if assignedTo, ok := activity.Metadata["assigned_to"].(string); ok && assignedTo == userID {
-			title = "Bead Assigned to You"
-			message = fmt.Sprintf("You've been assigned to bead: %s", activity.ResourceTitle)
-			link = fmt.Sprintf("/beads/%s", activity.ResourceID)
-			return
-		}
-		return "", "", ""
-	}
+	return m.formatNotificationForChannel(activity, userID, ChannelInApp)
+}
 
-	// Check for decision requiring user input
-	if activity.EventType == "decision.created" {
-		if deciderID, ok := activity.Metadata["decider_id"].(string); ok && deciderID == userID {
-			title = "Decision Requires Your Input"
-			message = fmt.Sprintf("A decision needs your attention: %s", activity.ResourceTitle)
-			link = fmt.Sprintf("/decisions/%s", activity.ResourceID)
-			return
+// formatNotificationForChannel formats a notification for a delivery channel
+func (m *Manager) formatNotificationForChannel(activity *activity.Activity, userID, channel string) (title, message, link string) {
+	switch activity.EventType {
+	case "bead.assigned":
+		// Only notify the assignee
+		if assignedTo, ok := activity.Metadata["assigned_to"].(string); !ok || assignedTo != userID {
+			return "", "", ""
 		}
-		return "", "", ""
-	}
-
-	// Check for critical priority beads
-	if activity.EventType == "bead.created" {
-		if priority, ok := activity.Metadata["priority"].(string); ok && priority == "P0" {
-			title = "Critical Bead Created"
-			message = fmt.Sprintf("A P0 bead was created: %s", activity.ResourceTitle)
-			link = fmt.Sprintf("/beads/%s", activity.ResourceID)
-			return
+	case "decision.created":
+		// Only notify the decider
+		if deciderID, ok := activity.Metadata["decider_id"].(string); !ok || deciderID != userID {
+			return "", "", ""
 		}
+	case "bead.created":
+		// Only critical priority beads
+		if priority, ok := activity.Metadata["priority"].(string); !ok || priority != "P0" {
+			return "", "", ""
+		}
+	case "provider.deleted", "workflow.failed", "golden_prompts.regression":
+		// System alerts go to everyone
+	default:
+		return "", "", ""
 	}
 
-	// Check for system errors
-	if activity.EventType == "provider.deleted" || activity.EventType == "workflow.failed" {
-		title = "System Alert"
-		message = fmt.Sprintf("%s: %s", activity.Action, activity.ResourceTitle)
-		link = fmt.Sprintf("/%ss/%s", activity.ResourceType, activity.ResourceID)
-		return
-	}
-
-	return "", "", ""
+	return m.renderNotification(org.Normalize(activity.OrgID), activity, userID, channel)
 }
 
 // determinePriority determines notification priority based on activity
@@ -198,7 +235,7 @@ func (m *Manager) determinePriority(activity *activity.Activity) string {
 	switch activity.EventType {
 	case "bead.assigned", "decision.created":
 		return PriorityHigh
-	case "workflow.failed", "provider.deleted":
+	case "workflow.failed", "provider.deleted", "golden_prompts.regression":
 		return PriorityCritical
 	case "bead.created", "agent.spawned":
 		return PriorityNormal
@@ -222,33 +259,10 @@ func (m *Manager) isEventSubscribed(eventType string, subscribedEvents []string)
 	return false
 }
 
-// inQuietHours checks if current time is in quiet hours
-func (m *Manager) inQuietHours(prefs *NotificationPreferences) bool {
-	if prefs.QuietHoursStart == "" || prefs.QuietHoursEnd == "" {
-		return false
-	}
-
-	// Parse quiet hours
-	start, err := time.Parse("15:04", prefs.QuietHoursStart)
-	if err != nil {
-		return false
-	}
-
-	end, err := time.Parse("15:04", prefs.QuietHoursEnd)
-	if err != nil {
-		return false
-	}
-
-	// Get current time (hours and minutes only)
-	now := time.Now()
-	currentTime := time.Date(0, 1, 1, now.Hour(), now.Minute(), 0, 0, time.UTC)
-
-	// Handle quiet hours spanning midnight
-	if start.Before(end) {
-		return currentTime.After(start) && currentTime.Before(end)
-	} else {
-		return currentTime.After(start) || currentTime.Before(end)
-	}
+// inQuietHours checks if a notification of a priority is held back on a
+// channel right now, in the user's time zone
+func (m *Manager) inQuietHours(prefs *NotificationPreferences, channelPrefs ChannelPreferences, priority string) bool {
+	return prefs.quietAt(channelPrefs, priority, time.Now())
 }
 
 // meetsPriorityThreshold checks if notification priority meets user's threshold
@@ -266,7 +280,8 @@ func (m *Manager) meetsPriorityThreshold(notificationPriority, minPriority strin
 	return notifLevel >= minLevel
 }
 
-// CreateNotification creates a new notification
+// CreateNotification creates a new notification, streaming the change to
+// the user's unread counts when it is unread
 func (m *Manager) CreateNotification(notification *Notification) error {
 	// Convert metadata to JSON
 	var metadataJSON string
@@ -282,6 +297,7 @@ func (m *Manager) CreateNotification(notification *Notification) error {
 		ID:           notification.ID,
 		UserID:       notification.UserID,
 		ActivityID:   notification.ActivityID,
+		ProjectID:    notification.ProjectID,
 		EventType:    notification.EventType,
 		Title:        notification.Title,
 		Message:      notification.Message,
@@ -292,9 +308,21 @@ func (m *Manager) CreateNotification(notification *Notification) error {
 		CreatedAt:    notification.CreatedAt,
 		ReadAt:       notification.ReadAt,
 		ArchivedAt:   notification.ArchivedAt,
+		SnoozedUntil: notification.SnoozedUntil,
 	}
 
-	return m.db.CreateNotification(dbNotification)
+	if err := m.db.CreateNotification(dbNotification); err != nil {
+		return err
+	}
+	if notification.Status == StatusUnread {
+		m.publishCounts([]database.UnreadCount{{
+			UserID:    notification.UserID,
+			ProjectID: notification.ProjectID,
+			Priority:  notification.Priority,
+			Count:     1,
+		}})
+	}
+	return nil
 }
 
 // GetNotifications retrieves notifications for a user
@@ -303,22 +331,41 @@ func (m *Manager) GetNotifications(userID string, status string, limit, offset i
 	if err != nil {
 		return nil, err
 	}
+	return fromDBNotifications(dbNotifications), nil
+}
 
+// QueryNotifications returns the page of a user's notifications selected by
+// filters and how many match in all
+func (m *Manager) QueryNotifications(userID string, filters database.NotificationFilters) ([]*Notification, int, error) {
+	total, err := m.db.CountNotifications(userID, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	dbNotifications, err := m.db.QueryNotifications(userID, filters)
+	if err != nil {
+		return nil, 0, err
+	}
+	return fromDBNotifications(dbNotifications), total, nil
+}
+
+func fromDBNotifications(dbNotifications []*database.Notification) []*Notification {
 	notifications := make([]*Notification, 0, len(dbNotifications))
 	for _, dbNotif := range dbNotifications {
 		notification := &Notification{
-			ID:         dbNotif.ID,
-			UserID:     dbNotif.UserID,
-			ActivityID: dbNotif.ActivityID,
-			EventType:  dbNotif.EventType,
-			Title:      dbNotif.Title,
-			Message:    dbNotif.Message,
-			Link:       dbNotif.Link,
-			Status:     dbNotif.Status,
-			Priority:   dbNotif.Priority,
-			CreatedAt:  dbNotif.CreatedAt,
-			ReadAt:     dbNotif.ReadAt,
-			ArchivedAt: dbNotif.ArchivedAt,
+			ID:           dbNotif.ID,
+			UserID:       dbNotif.UserID,
+			ActivityID:   dbNotif.ActivityID,
+			ProjectID:    dbNotif.ProjectID,
+			EventType:    dbNotif.EventType,
+			Title:        dbNotif.Title,
+			Message:      dbNotif.Message,
+			Link:         dbNotif.Link,
+			Status:       dbNotif.Status,
+			Priority:     dbNotif.Priority,
+			CreatedAt:    dbNotif.CreatedAt,
+			ReadAt:       dbNotif.ReadAt,
+			ArchivedAt:   dbNotif.ArchivedAt,
+			SnoozedUntil: dbNotif.SnoozedUntil,
 		}
 
 		// Parse metadata JSON
@@ -332,17 +379,37 @@ func (m *Manager) GetNotifications(userID string, status string, limit, offset i
 		notifications = append(notifications, notification)
 	}
 
-	return notifications, nil
+	return notifications
 }
 
 // MarkRead marks a notification as read
 func (m *Manager) MarkRead(notificationID string) error {
-	return m.db.MarkNotificationRead(notificationID)
+	changes, err := m.db.MarkNotificationRead(notificationID)
+	if err != nil {
+		return err
+	}
+	m.publishCounts(changes)
+	return nil
 }
 
 // MarkAllRead marks all unread notifications as read for a user
 func (m *Manager) MarkAllRead(userID string) error {
-	return m.db.MarkAllNotificationsRead(userID)
+	changes, err := m.db.MarkAllNotificationsRead(userID)
+	if err != nil {
+		return err
+	}
+	m.publishCounts(changes)
+	return nil
+}
+
+// Archive archives one of a user's notifications
+func (m *Manager) Archive(notificationID, userID string) error {
+	changes, err := m.db.ArchiveNotification(notificationID, userID)
+	if err != nil {
+		return err
+	}
+	m.publishCounts(changes)
+	return nil
 }
 
 // GetPreferences retrieves notification preferences for a user
@@ -358,16 +425,19 @@ func (m *Manager) GetPreferences(userID string) (*NotificationPreferences, error
 	}
 
 	prefs := &NotificationPreferences{
-		ID:              dbPrefs.ID,
-		UserID:          dbPrefs.UserID,
-		EnableInApp:     dbPrefs.EnableInApp,
-		EnableEmail:     dbPrefs.EnableEmail,
-		EnableWebhook:   dbPrefs.EnableWebhook,
-		DigestMode:      dbPrefs.DigestMode,
-		QuietHoursStart: dbPrefs.QuietHoursStart,
-		QuietHoursEnd:   dbPrefs.QuietHoursEnd,
-		MinPriority:     dbPrefs.MinPriority,
-		UpdatedAt:       dbPrefs.UpdatedAt,
+		ID:                      dbPrefs.ID,
+		UserID:                  dbPrefs.UserID,
+		EnableInApp:             dbPrefs.EnableInApp,
+		EnableEmail:             dbPrefs.EnableEmail,
+		EnableWebhook:           dbPrefs.EnableWebhook,
+		EnablePush:              dbPrefs.EnablePush,
+		DigestMode:              dbPrefs.DigestMode,
+		QuietHoursStart:         dbPrefs.QuietHoursStart,
+		QuietHoursEnd:           dbPrefs.QuietHoursEnd,
+		Timezone:                dbPrefs.Timezone,
+		QuietHoursAllowCritical: dbPrefs.QuietHoursAllowCritical,
+		MinPriority:             dbPrefs.MinPriority,
+		UpdatedAt:               dbPrefs.UpdatedAt,
 	}
 
 	// Parse JSON fields
@@ -385,6 +455,27 @@ func (m *Manager) GetPreferences(userID string) (*NotificationPreferences, error
 		}
 	}
 
+	if dbPrefs.ChannelsJSON != "" {
+		var channels map[string]*ChannelPreferences
+		if err := json.Unmarshal([]byte(dbPrefs.ChannelsJSON), &channels); err == nil {
+			prefs.Channels = channels
+		}
+	}
+
+	if dbPrefs.QuietScheduleJSON != "" {
+		var schedule []*QuietWindow
+		if err := json.Unmarshal([]byte(dbPrefs.QuietScheduleJSON), &schedule); err == nil {
+			prefs.QuietSchedule = schedule
+		}
+	}
+
+	if dbPrefs.MuteRulesJSON != "" {
+		var rules []*MuteRule
+		if err := json.Unmarshal([]byte(dbPrefs.MuteRulesJSON), &rules); err == nil {
+			prefs.MuteRules = rules
+		}
+	}
+
 	return prefs, nil
 }
 
@@ -396,6 +487,7 @@ func (m *Manager) createDefaultPreferences(userID string) (*NotificationPreferen
 		EnableInApp:      true,
 		EnableEmail:      false,
 		EnableWebhook:    false,
+		EnablePush:       false,
 		SubscribedEvents: []string{}, // Subscribe to all by default
 		DigestMode:       DigestRealtime,
 		MinPriority:      PriorityNormal,
@@ -412,8 +504,12 @@ func (m *Manager) createDefaultPreferences(userID string) (*NotificationPreferen
 
 // UpdatePreferences updates notification preferences
 func (m *Manager) UpdatePreferences(prefs *NotificationPreferences) error {
+	if err := prefs.Validate(); err != nil {
+		return err
+	}
+
 	// Convert to DB format
-	var subscribedEventsJSON, projectFiltersJSON string
+	var subscribedEventsJSON, projectFiltersJSON, channelsJSON, quietScheduleJSON, muteRulesJSON string
 
 	if len(prefs.SubscribedEvents) > 0 {
 		data, err := json.Marshal(prefs.SubscribedEvents)
@@ -431,21 +527,57 @@ func (m *Manager) UpdatePreferences(prefs *NotificationPreferences) error {
 		projectFiltersJSON = string(data)
 	}
 
+	if len(prefs.Channels) > 0 {
+		data, err := json.Marshal(prefs.Channels)
+		if err != nil {
+			return fmt.Errorf("failed to marshal channel preferences: %w", err)
+		}
+		channelsJSON = string(data)
+	}
+
+	if len(prefs.QuietSchedule) > 0 {
+		data, err := json.Marshal(prefs.QuietSchedule)
+		if err != nil {
+			return fmt.Errorf("failed to marshal quiet schedule: %w", err)
+		}
+		quietScheduleJSON = string(data)
+	}
+
+	for _, rule := range prefs.MuteRules {
+		if rule != nil && rule.ID == "" {
+			rule.ID = uuid.New().String()
+			rule.CreatedAt = time.Now()
+		}
+	}
+	if len(prefs.MuteRules) > 0 {
+		data, err := json.Marshal(prefs.MuteRules)
+		if err != nil {
+			return fmt.Errorf("failed to marshal mute rules: %w", err)
+		}
+		muteRulesJSON = string(data)
+	}
+
 	prefs.UpdatedAt = time.Now()
 
 	dbPrefs := &database.NotificationPreferences{
-		ID:                   prefs.ID,
-		UserID:               prefs.UserID,
-		EnableInApp:          prefs.EnableInApp,
-		EnableEmail:          prefs.EnableEmail,
-		EnableWebhook:        prefs.EnableWebhook,
-		SubscribedEventsJSON: subscribedEventsJSON,
-		DigestMode:           prefs.DigestMode,
-		QuietHoursStart:      prefs.QuietHoursStart,
-		QuietHoursEnd:        prefs.QuietHoursEnd,
-		ProjectFiltersJSON:   projectFiltersJSON,
-		MinPriority:          prefs.MinPriority,
-		UpdatedAt:            prefs.UpdatedAt,
+		ID:                      prefs.ID,
+		UserID:                  prefs.UserID,
+		EnableInApp:             prefs.EnableInApp,
+		EnableEmail:             prefs.EnableEmail,
+		EnableWebhook:           prefs.EnableWebhook,
+		EnablePush:              prefs.EnablePush,
+		SubscribedEventsJSON:    subscribedEventsJSON,
+		DigestMode:              prefs.DigestMode,
+		QuietHoursStart:         prefs.QuietHoursStart,
+		QuietHoursEnd:           prefs.QuietHoursEnd,
+		QuietScheduleJSON:       quietScheduleJSON,
+		Timezone:                prefs.Timezone,
+		QuietHoursAllowCritical: prefs.QuietHoursAllowCritical,
+		ProjectFiltersJSON:      projectFiltersJSON,
+		MinPriority:             prefs.MinPriority,
+		ChannelsJSON:            channelsJSON,
+		MuteRulesJSON:           muteRulesJSON,
+		UpdatedAt:               prefs.UpdatedAt,
 	}
 
 	return m.db.UpsertNotificationPreferences(dbPrefs)
@@ -481,6 +613,16 @@ func (m *Manager) Unsubscribe(userID, subscriberID string) {
 			delete(m.subscribers, userID)
 		}
 	}
+
+	if userSubs, exists := m.countSubscribers[userID]; exists {
+		if ch, exists := userSubs[subscriberID]; exists {
+			close(ch)
+			delete(userSubs, subscriberID)
+		}
+		if len(userSubs) == 0 {
+			delete(m.countSubscribers, userID)
+		}
+	}
 }
 
 // broadcastToUser sends a notification to all of a user's subscribers
@@ -0,0 +1,188 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/activity"
+)
+
+// Mute rule scopes
+const (
+	MuteBead      = "bead"
+	MuteProject   = "project"
+	MuteEventType = "event_type"
+)
+
+// MuteRule silences a user's activity notifications about one bead, one
+// project or one event type, for good or until Until
+type MuteRule struct {
+	ID        string     `json:"id"`
+	Scope     string     `json:"scope"` // MuteBead, MuteProject or MuteEventType
+	Value     string     `json:"value"` // Bead ID, project ID or event type
+	Until     *time.Time `json:"until,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// expired reports whether a timed rule has run out by now
+func (r *MuteRule) expired(now time.Time) bool {
+	return r.Until != nil && !now.Before(*r.Until)
+}
+
+// Matches reports whether the rule silences act at now
+func (r *MuteRule) Matches(act *activity.Activity, now time.Time) bool {
+	if r.expired(now) {
+		return false
+	}
+	switch r.Scope {
+	case MuteBead:
+		return act.BeadID == r.Value || (act.ResourceType == "bead" && act.ResourceID == r.Value)
+	case MuteProject:
+		return act.ProjectID == r.Value
+	case MuteEventType:
+		return act.EventType == r.Value
+	default:
+		return false
+	}
+}
+
+func (r *MuteRule) validate() error {
+	switch r.Scope {
+	case MuteBead, MuteProject, MuteEventType:
+	default:
+		return fmt.Errorf("invalid mute scope %q (expected bead, project or event_type)", r.Scope)
+	}
+	if r.Value == "" {
+		return fmt.Errorf("mute rule for %s requires a value", r.Scope)
+	}
+	return nil
+}
+
+// Muted reports whether one of the user's mute rules silences act at now
+func (p *NotificationPreferences) Muted(act *activity.Activity, now time.Time) bool {
+	for _, rule := range p.MuteRules {
+		if rule != nil && rule.Matches(act, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeMuteRules returns the rules that have not expired by now
+func activeMuteRules(rules []*MuteRule, now time.Time) []*MuteRule {
+	active := make([]*MuteRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule != nil && !rule.expired(now) {
+			active = append(active, rule)
+		}
+	}
+	return active
+}
+
+// MuteRules returns a user's mute rules that have not expired
+func (m *Manager) MuteRules(userID string) ([]*MuteRule, error) {
+	prefs, err := m.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+	return activeMuteRules(prefs.MuteRules, time.Now()), nil
+}
+
+// AddMuteRule adds a mute rule to a user's preferences, dropping the rules
+// that have expired. A rule with the scope and value of an existing one
+// replaces it.
+func (m *Manager) AddMuteRule(userID string, rule *MuteRule) (*MuteRule, error) {
+	if err := rule.validate(); err != nil {
+		return nil, err
+	}
+	prefs, err := m.GetPreferences(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rule.ID = uuid.New().String()
+	rule.CreatedAt = now
+	rules := []*MuteRule{}
+	for _, r := range activeMuteRules(prefs.MuteRules, now) {
+		if r.Scope != rule.Scope || r.Value != rule.Value {
+			rules = append(rules, r)
+		}
+	}
+	prefs.MuteRules = append(rules, rule)
+	if err := m.UpdatePreferences(prefs); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// RemoveMuteRule removes one of a user's mute rules
+func (m *Manager) RemoveMuteRule(userID, ruleID string) error {
+	prefs, err := m.GetPreferences(userID)
+	if err != nil {
+		return err
+	}
+	rules := make([]*MuteRule, 0, len(prefs.MuteRules))
+	for _, r := range prefs.MuteRules {
+		if r != nil && r.ID != ruleID {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == len(prefs.MuteRules) {
+		return fmt.Errorf("mute rule not found: %s", ruleID)
+	}
+	prefs.MuteRules = rules
+	return m.UpdatePreferences(prefs)
+}
+
+// snoozeCheckInterval is how often snoozed notifications are woken
+var snoozeCheckInterval = time.Minute
+
+// Snooze hides one of a user's notifications for d, after which it comes
+// back unread and is streamed to the user again
+func (m *Manager) Snooze(notificationID, userID string, d time.Duration) (time.Time, error) {
+	if d <= 0 {
+		return time.Time{}, fmt.Errorf("snooze duration must be positive")
+	}
+	until := time.Now().Add(d)
+	changes, err := m.db.SnoozeNotification(notificationID, userID, until)
+	if err != nil {
+		return time.Time{}, err
+	}
+	m.publishCounts(changes)
+	return until, nil
+}
+
+// WakeSnoozed makes the notifications snoozed until now or earlier unread
+// again and streams them to their users, returning how many woke
+func (m *Manager) WakeSnoozed(now time.Time) (int, error) {
+	due, changes, err := m.db.WakeSnoozedNotifications(now)
+	if err != nil {
+		return 0, err
+	}
+	for _, n := range fromDBNotifications(due) {
+		m.broadcastToUser(n.UserID, n)
+	}
+	m.publishCounts(changes)
+	return len(due), nil
+}
+
+// wakeSnoozedLoop wakes snoozed notifications every snoozeCheckInterval
+// until ctx is done
+func (m *Manager) wakeSnoozedLoop(ctx context.Context) {
+	ticker := time.NewTicker(snoozeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := m.WakeSnoozed(now); err != nil {
+				log.Printf("Failed to wake snoozed notifications: %v", err)
+			}
+		}
+	}
+}
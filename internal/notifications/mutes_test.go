@@ -0,0 +1,127 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+)
+
+func TestMuteRuleMatches(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	act := &activity.Activity{EventType: "bead.assigned", ProjectID: "proj-1", ResourceType: "bead", ResourceID: "bead-1"}
+
+	tests := []struct {
+		rule MuteRule
+		want bool
+	}{
+		{MuteRule{Scope: MuteBead, Value: "bead-1"}, true},
+		{MuteRule{Scope: MuteBead, Value: "bead-2"}, false},
+		{MuteRule{Scope: MuteProject, Value: "proj-1"}, true},
+		{MuteRule{Scope: MuteEventType, Value: "bead.assigned"}, true},
+		{MuteRule{Scope: MuteEventType, Value: "bead.created"}, false},
+		{MuteRule{Scope: MuteProject, Value: "proj-1", Until: &past}, false},
+	}
+	for i, tt := range tests {
+		if got := tt.rule.Matches(act, now); got != tt.want {
+			t.Errorf("case %d: Matches = %v, want %v", i, got, tt.want)
+		}
+	}
+
+	invalid := []*NotificationPreferences{
+		{MuteRules: []*MuteRule{{Scope: "agent", Value: "agent-1"}}},
+		{MuteRules: []*MuteRule{{Scope: MuteBead}}},
+	}
+	for i, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+}
+
+func TestProcessActivity_MuteRules(t *testing.T) {
+	m := newTestManager(t)
+	assigned := func(beadID string) *activity.Activity {
+		return &activity.Activity{
+			EventType:     "bead.assigned",
+			ResourceType:  "bead",
+			ResourceID:    beadID,
+			ResourceTitle: "Fix login",
+			Metadata:      map[string]interface{}{"assigned_to": "user-admin"},
+		}
+	}
+
+	rule, err := m.AddMuteRule("user-admin", &MuteRule{Scope: MuteBead, Value: "bead-1"})
+	if err != nil {
+		t.Fatalf("AddMuteRule failed: %v", err)
+	}
+	if ok, _ := m.ShouldNotify(assigned("bead-1"), "user-admin"); ok {
+		t.Error("expected the muted bead not to notify")
+	}
+	if ok, _ := m.ShouldNotify(assigned("bead-2"), "user-admin"); !ok {
+		t.Error("expected other beads to notify")
+	}
+
+	// Adding the same rule again replaces it
+	if _, err := m.AddMuteRule("user-admin", &MuteRule{Scope: MuteBead, Value: "bead-1"}); err != nil {
+		t.Fatalf("AddMuteRule failed: %v", err)
+	}
+	rules, err := m.MuteRules("user-admin")
+	if err != nil || len(rules) != 1 {
+		t.Fatalf("MuteRules = %+v, %v", rules, err)
+	}
+	if err := m.RemoveMuteRule("user-admin", rule.ID); err == nil {
+		t.Error("expected the replaced rule to be gone")
+	}
+	if err := m.RemoveMuteRule("user-admin", rules[0].ID); err != nil {
+		t.Fatalf("RemoveMuteRule failed: %v", err)
+	}
+	if ok, _ := m.ShouldNotify(assigned("bead-1"), "user-admin"); !ok {
+		t.Error("expected the bead to notify once unmuted")
+	}
+}
+
+func TestSnoozeAndWake(t *testing.T) {
+	m := newTestManager(t)
+	created := time.Now().Add(-time.Hour)
+	if err := m.CreateNotification(&Notification{
+		ID: "notif-1", UserID: "user-admin", EventType: "bead.assigned", Title: "T",
+		Message: "M", Status: StatusRead, Priority: PriorityHigh, CreatedAt: created, ReadAt: &created,
+	}); err != nil {
+		t.Fatalf("CreateNotification failed: %v", err)
+	}
+	stream := m.Subscribe("user-admin", "test")
+
+	if _, err := m.Snooze("notif-1", "user-other", time.Hour); err == nil {
+		t.Error("expected another user's notification not to be snoozed")
+	}
+	until, err := m.Snooze("notif-1", "user-admin", time.Hour)
+	if err != nil {
+		t.Fatalf("Snooze failed: %v", err)
+	}
+	snoozed, _ := m.GetNotifications("user-admin", StatusSnoozed, 10, 0)
+	if len(snoozed) != 1 || snoozed[0].SnoozedUntil == nil {
+		t.Fatalf("expected one snoozed notification, got %+v", snoozed)
+	}
+
+	if n, err := m.WakeSnoozed(time.Now()); err != nil || n != 0 {
+		t.Fatalf("expected nothing to wake yet, got %d (err %v)", n, err)
+	}
+	if n, err := m.WakeSnoozed(until.Add(time.Second)); err != nil || n != 1 {
+		t.Fatalf("expected one notification to wake, got %d (err %v)", n, err)
+	}
+	select {
+	case n := <-stream:
+		if n.ID != "notif-1" || n.Status != StatusUnread {
+			t.Errorf("expected the woken notification streamed unread, got %+v", n)
+		}
+	default:
+		t.Error("expected the woken notification to be streamed")
+	}
+	unread, _, _ := m.QueryNotifications("user-admin", database.NotificationFilters{Status: StatusUnread})
+	if len(unread) != 1 || unread[0].ReadAt != nil || unread[0].SnoozedUntil != nil {
+		t.Errorf("expected the notification unread again, got %+v", unread)
+	}
+}
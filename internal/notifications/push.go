@@ -0,0 +1,217 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// Push platforms
+const (
+	PlatformFCM  = "fcm"
+	PlatformAPNs = "apns"
+)
+
+// pushTimeout bounds each message sent to FCM or APNs
+const pushTimeout = 15 * time.Second
+
+// ErrDeviceUnregistered is returned by a PushSender when the push service
+// no longer knows a device token, as after the app is uninstalled. The
+// device is then removed.
+var ErrDeviceUnregistered = errors.New("device token is no longer registered")
+
+// Device is a phone or tablet registered for a user's push notifications
+type Device struct {
+	ID         string    `json:"id"`
+	UserID     string    `json:"user_id"`
+	Platform   string    `json:"platform"` // PlatformFCM or PlatformAPNs
+	Token      string    `json:"token"`
+	Name       string    `json:"name,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// PushMessage is a notification as sent to a device
+type PushMessage struct {
+	Title       string
+	Body        string
+	Priority    string
+	CollapseKey string            // Messages with the same key replace each other on the device; empty never collapses
+	Data        map[string]string // Read by the app: notification_id, event_type, priority, link and url
+}
+
+// Urgent reports whether the message should wake the device
+func (msg *PushMessage) Urgent() bool {
+	return msg.Priority == PriorityHigh || msg.Priority == PriorityCritical
+}
+
+// PushSender sends messages to device tokens of one platform
+type PushSender interface {
+	Send(ctx context.Context, token string, msg *PushMessage) error
+}
+
+// pushCollapseKey is the collapse key of a priority. Critical notifications
+// are pages, so each is shown; at lower priorities a device keeps only the
+// latest notification of each priority.
+func pushCollapseKey(priority string) string {
+	if priority == PriorityCritical {
+		return ""
+	}
+	return "loom-" + priority
+}
+
+// newPushMessage builds the message for a notification. The deep link is
+// the notification's Link, as the web UI uses it, and that link made
+// absolute against baseURL when one is set.
+func newPushMessage(n *Notification, baseURL string) *PushMessage {
+	msg := &PushMessage{
+		Title:       n.Title,
+		Body:        n.Message,
+		Priority:    n.Priority,
+		CollapseKey: pushCollapseKey(n.Priority),
+		Data: map[string]string{
+			"notification_id": n.ID,
+			"event_type":      n.EventType,
+			"priority":        n.Priority,
+		},
+	}
+	if n.ProjectID != "" {
+		msg.Data["project_id"] = n.ProjectID
+	}
+	if n.Link != "" {
+		msg.Data["link"] = n.Link
+		if baseURL != "" && strings.HasPrefix(n.Link, "/") {
+			msg.Data["url"] = strings.TrimSuffix(baseURL, "/") + n.Link
+		} else if strings.HasPrefix(n.Link, "https://") || strings.HasPrefix(n.Link, "http://") {
+			msg.Data["url"] = n.Link
+		}
+	}
+	return msg
+}
+
+// PushDeliverer delivers the push channel to every device a user has
+// registered
+type PushDeliverer struct {
+	db      *database.Database
+	baseURL string
+	senders map[string]PushSender // platform -> sender
+}
+
+// NewPushDeliverer creates a deliverer sending through the push services
+// configured in cfg
+func NewPushDeliverer(db *database.Database, cfg config.PushConfig) (*PushDeliverer, error) {
+	p := &PushDeliverer{db: db, baseURL: cfg.BaseURL, senders: make(map[string]PushSender)}
+	if cfg.FCM != nil {
+		sender, err := NewFCMSender(*cfg.FCM)
+		if err != nil {
+			return nil, err
+		}
+		p.senders[PlatformFCM] = sender
+	}
+	if cfg.APNs != nil {
+		sender, err := NewAPNsSender(*cfg.APNs)
+		if err != nil {
+			return nil, err
+		}
+		p.senders[PlatformAPNs] = sender
+	}
+	return p, nil
+}
+
+// Deliver sends a notification to each of its user's devices. Devices whose
+// tokens the push service rejects as unregistered are removed.
+func (p *PushDeliverer) Deliver(n *Notification) error {
+	devices, err := p.db.ListNotificationDevices(n.UserID)
+	if err != nil {
+		return err
+	}
+	msg := newPushMessage(n, p.baseURL)
+
+	var errs []error
+	for _, device := range devices {
+		sender := p.senders[device.Platform]
+		if sender == nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), pushTimeout)
+		err := sender.Send(ctx, device.Token, msg)
+		cancel()
+		if errors.Is(err, ErrDeviceUnregistered) {
+			log.Printf("Removing unregistered %s device %s of user %s", device.Platform, device.ID, n.UserID)
+			if err := p.db.DeleteNotificationDeviceByToken(device.Token); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("device %s: %w", device.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func isValidPlatform(platform string) bool {
+	return platform == PlatformFCM || platform == PlatformAPNs
+}
+
+// RegisterDevice registers a device token for a user's push notifications.
+// A token registered before keeps its ID and moves to the user.
+func (m *Manager) RegisterDevice(userID, platform, token, name string) (*Device, error) {
+	if !isValidPlatform(platform) {
+		return nil, fmt.Errorf("invalid platform %q (expected fcm or apns)", platform)
+	}
+	if strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("device token is required")
+	}
+	now := time.Now()
+	device := &database.NotificationDevice{
+		ID:         uuid.New().String(),
+		UserID:     userID,
+		Platform:   platform,
+		Token:      token,
+		Name:       name,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := m.db.SaveNotificationDevice(device); err != nil {
+		return nil, err
+	}
+	return fromDBDevice(device), nil
+}
+
+// Devices returns the devices a user registered
+func (m *Manager) Devices(userID string) ([]*Device, error) {
+	rows, err := m.db.ListNotificationDevices(userID)
+	if err != nil {
+		return nil, err
+	}
+	devices := make([]*Device, 0, len(rows))
+	for _, row := range rows {
+		devices = append(devices, fromDBDevice(row))
+	}
+	return devices, nil
+}
+
+// RemoveDevice stops push notifications to one of a user's devices
+func (m *Manager) RemoveDevice(userID, deviceID string) error {
+	return m.db.DeleteNotificationDevice(deviceID, userID)
+}
+
+func fromDBDevice(d *database.NotificationDevice) *Device {
+	return &Device{
+		ID:         d.ID,
+		UserID:     d.UserID,
+		Platform:   d.Platform,
+		Token:      d.Token,
+		Name:       d.Name,
+		CreatedAt:  d.CreatedAt,
+		LastSeenAt: d.LastSeenAt,
+	}
+}
@@ -0,0 +1,153 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+const (
+	apnsEndpoint        = "https://api.push.apple.com"
+	apnsSandboxEndpoint = "https://api.sandbox.push.apple.com"
+
+	// apnsTokenLifetime is how long a provider token is used. APNs rejects
+	// tokens older than an hour and throttles ones renewed more often than
+	// every 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNsSender sends push notifications through the Apple Push Notification
+// service, over HTTP/2 with a token signed by the team's .p8 key
+type APNsSender struct {
+	keyID    string
+	teamID   string
+	topic    string
+	key      *ecdsa.PrivateKey
+	endpoint string
+	client   *http.Client
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// NewAPNsSender creates a sender from a token signing key
+func NewAPNsSender(cfg config.APNsConfig) (*APNsSender, error) {
+	data, err := os.ReadFile(cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("apns: %w", err)
+	}
+	key, err := jwt.ParseECPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("apns: %s: %w", cfg.KeyFile, err)
+	}
+	endpoint := apnsEndpoint
+	if cfg.Sandbox {
+		endpoint = apnsSandboxEndpoint
+	}
+	return &APNsSender{
+		keyID:    cfg.KeyID,
+		teamID:   cfg.TeamID,
+		topic:    cfg.Topic,
+		key:      key,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: pushTimeout},
+	}, nil
+}
+
+// Send sends msg to one device token. Urgent messages are delivered
+// immediately and break through Focus as time-sensitive; others may be
+// delayed to save power. The collapse key becomes apns-collapse-id.
+func (a *APNsSender) Send(ctx context.Context, token string, msg *PushMessage) error {
+	aps := map[string]interface{}{
+		"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+		"sound": "default",
+	}
+	priority := "5"
+	if msg.Urgent() {
+		priority = "10"
+		aps["interruption-level"] = "time-sensitive"
+	}
+	payload := map[string]interface{}{"aps": aps}
+	for k, v := range msg.Data {
+		payload[k] = v
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", priority)
+	if msg.CollapseKey != "" {
+		req.Header.Set("apns-collapse-id", msg.CollapseKey)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("apns: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(data, &failure)
+	switch {
+	case resp.StatusCode == http.StatusGone, failure.Reason == "BadDeviceToken", failure.Reason == "Unregistered":
+		return ErrDeviceUnregistered
+	case failure.Reason != "":
+		return fmt.Errorf("apns: %s: %s", resp.Status, failure.Reason)
+	default:
+		return fmt.Errorf("apns: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+}
+
+// providerToken returns the signed provider token, renewing it every
+// apnsTokenLifetime
+func (a *APNsSender) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.token != "" && now.Before(a.expires) {
+		return a.token, nil
+	}
+	t := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	t.Header["kid"] = a.keyID
+	signed, err := t.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("apns: provider token: %w", err)
+	}
+	a.token = signed
+	a.expires = now.Add(apnsTokenLifetime)
+	return a.token, nil
+}
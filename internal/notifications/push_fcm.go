@@ -0,0 +1,205 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+const (
+	fcmEndpoint = "https://fcm.googleapis.com"
+	fcmScope    = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCMSender sends push notifications through the Firebase Cloud Messaging
+// HTTP v1 API, authenticating as a service account
+type FCMSender struct {
+	projectID string
+	endpoint  string
+	client    *http.Client
+	account   fcmServiceAccount
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// fcmServiceAccount is the part of a service account key file used to
+// request tokens
+type fcmServiceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// NewFCMSender creates a sender from a service account key file
+func NewFCMSender(cfg config.FCMConfig) (*FCMSender, error) {
+	data, err := os.ReadFile(cfg.CredentialsFile)
+	if err != nil {
+		return nil, fmt.Errorf("fcm: %w", err)
+	}
+	var sa fcmServiceAccount
+	if err := json.Unmarshal(data, &sa); err != nil {
+		return nil, fmt.Errorf("fcm: %s: %w", cfg.CredentialsFile, err)
+	}
+	if sa.Type != "service_account" || sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("fcm: %s is not a service account key file", cfg.CredentialsFile)
+	}
+	if sa.TokenURI == "" {
+		sa.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	projectID := cfg.ProjectID
+	if projectID == "" {
+		projectID = sa.ProjectID
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("fcm: no project_id configured or in %s", cfg.CredentialsFile)
+	}
+	return &FCMSender{
+		projectID: projectID,
+		endpoint:  fcmEndpoint,
+		client:    &http.Client{Timeout: pushTimeout},
+		account:   sa,
+	}, nil
+}
+
+// Send sends msg to one registration token. Urgent messages go at high
+// priority so they wake Android devices in Doze; the collapse key lets a
+// newer message replace an undelivered older one.
+func (f *FCMSender) Send(ctx context.Context, token string, msg *PushMessage) error {
+	android := map[string]interface{}{"priority": "NORMAL"}
+	apnsHeaders := map[string]string{"apns-priority": "5"}
+	if msg.Urgent() {
+		android["priority"] = "HIGH"
+		apnsHeaders["apns-priority"] = "10"
+	}
+	if msg.CollapseKey != "" {
+		android["collapse_key"] = msg.CollapseKey
+		apnsHeaders["apns-collapse-id"] = msg.CollapseKey
+	}
+	message := map[string]interface{}{
+		"token":        token,
+		"notification": map[string]string{"title": msg.Title, "body": msg.Body},
+		"data":         msg.Data,
+		"android":      android,
+		"apns":         map[string]interface{}{"headers": apnsHeaders},
+	}
+	if link := msg.Data["url"]; strings.HasPrefix(link, "https://") {
+		message["webpush"] = map[string]interface{}{"fcm_options": map[string]string{"link": link}}
+	}
+	body, err := json.Marshal(map[string]interface{}{"message": message})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := f.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		fmt.Sprintf("%s/v1/projects/%s/messages:send", f.endpoint, url.PathEscape(f.projectID)), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fcm: %w", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+
+	var failure struct {
+		Error struct {
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	_ = json.Unmarshal(data, &failure)
+	for _, d := range failure.Error.Details {
+		if d.ErrorCode == "UNREGISTERED" {
+			return ErrDeviceUnregistered
+		}
+	}
+	return fmt.Errorf("fcm: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+}
+
+// accessToken returns an OAuth access token, exchanging a signed assertion
+// for a new one shortly before the cached one expires
+func (f *FCMSender) accessToken(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.token != "" && time.Until(f.expires) > time.Minute {
+		return f.token, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(f.account.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("fcm: service account key: %w", err)
+	}
+	now := time.Now()
+	grant := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.account.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.account.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	if f.account.PrivateKeyID != "" {
+		grant.Header["kid"] = f.account.PrivateKeyID
+	}
+	assertion, err := grant.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.account.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm: token request: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode/100 != 2 {
+		return "", fmt.Errorf("fcm: token request: %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(data, &tok); err != nil {
+		return "", fmt.Errorf("fcm: token response: %w", err)
+	}
+	f.token = tok.AccessToken
+	f.expires = now.Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return f.token, nil
+}
@@ -0,0 +1,158 @@
+package notifications
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+func TestNewPushMessage(t *testing.T) {
+	n := &Notification{ID: "n-1", EventType: "bead.created", Title: "P0 bead", Message: "Outage", Link: "/beads/bead-1", Priority: PriorityCritical}
+	msg := newPushMessage(n, "https://loom.example.com/")
+	if msg.CollapseKey != "" || !msg.Urgent() {
+		t.Errorf("expected critical messages urgent and never collapsed, got %+v", msg)
+	}
+	if msg.Data["link"] != "/beads/bead-1" || msg.Data["url"] != "https://loom.example.com/beads/bead-1" {
+		t.Errorf("unexpected deep link: %v", msg.Data)
+	}
+
+	n.Priority = PriorityNormal
+	msg = newPushMessage(n, "")
+	if msg.CollapseKey != "loom-normal" || msg.Urgent() {
+		t.Errorf("expected normal messages collapsed by priority, got %+v", msg)
+	}
+	if _, ok := msg.Data["url"]; ok {
+		t.Errorf("expected no absolute URL without a base URL, got %v", msg.Data)
+	}
+}
+
+func TestPushDeliverer(t *testing.T) {
+	m := newTestManager(t)
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	var fcmBodies []map[string]interface{}
+	var apnsHeaders []http.Header
+	var apnsBodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/token":
+			w.Write([]byte(`{"access_token":"fcm-access","expires_in":3600}`))
+		case r.URL.Path == "/v1/projects/proj-push/messages:send":
+			if r.Header.Get("Authorization") != "Bearer fcm-access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			fcmBodies = append(fcmBodies, body)
+			if body["message"].(map[string]interface{})["token"] == "fcm-gone" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"code":404,"status":"NOT_FOUND","details":[{"errorCode":"UNREGISTERED"}]}}`))
+				return
+			}
+			w.Write([]byte(`{"name":"projects/proj-push/messages/1"}`))
+		case strings.HasPrefix(r.URL.Path, "/3/device/"):
+			var body map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&body)
+			apnsHeaders = append(apnsHeaders, r.Header.Clone())
+			apnsBodies = append(apnsBodies, body)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	account, _ := json.Marshal(map[string]string{
+		"type": "service_account", "project_id": "proj-push", "client_email": "loom@proj-push.iam.gserviceaccount.com",
+		"private_key": string(rsaPEM), "token_uri": srv.URL + "/token",
+	})
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecDER, _ := x509.MarshalPKCS8PrivateKey(ecKey)
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	cfg := config.PushConfig{
+		BaseURL: "https://loom.example.com",
+		FCM:     &config.FCMConfig{CredentialsFile: writeFile("sa.json", account)},
+		APNs: &config.APNsConfig{
+			KeyFile: writeFile("key.p8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: ecDER})),
+			KeyID:   "KEY123", TeamID: "TEAM123", Topic: "com.example.loom",
+		},
+	}
+	push, err := NewPushDeliverer(m.db, cfg)
+	if err != nil {
+		t.Fatalf("NewPushDeliverer failed: %v", err)
+	}
+	push.senders[PlatformFCM].(*FCMSender).endpoint = srv.URL
+	push.senders[PlatformAPNs].(*APNsSender).endpoint = srv.URL
+	if err := m.RegisterDeliverer(ChannelPush, push); err != nil {
+		t.Fatalf("RegisterDeliverer failed: %v", err)
+	}
+
+	for _, d := range []struct{ platform, token string }{{PlatformFCM, "fcm-ok"}, {PlatformFCM, "fcm-gone"}, {PlatformAPNs, "apns-ok"}} {
+		if _, err := m.RegisterDevice("user-admin", d.platform, d.token, ""); err != nil {
+			t.Fatalf("RegisterDevice failed: %v", err)
+		}
+	}
+	if _, err := m.RegisterDevice("user-admin", "sms", "x", ""); err == nil {
+		t.Error("expected an unknown platform to be rejected")
+	}
+
+	prefs, _ := m.GetPreferences("user-admin")
+	prefs.EnableInApp = false
+	prefs.EnablePush = true
+	if err := m.UpdatePreferences(prefs); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+	if err := m.Notify(&Notification{UserID: "user-admin", EventType: "bead.created", Title: "P0 bead", Message: "Outage", Link: "/beads/bead-1", Priority: PriorityCritical}); err != nil {
+		t.Fatalf("Notify failed: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(fcmBodies) != 2 || len(apnsBodies) != 1 {
+		t.Fatalf("expected 2 FCM and 1 APNs messages, got %d and %d", len(fcmBodies), len(apnsBodies))
+	}
+	message := fcmBodies[0]["message"].(map[string]interface{})
+	if message["android"].(map[string]interface{})["priority"] != "HIGH" {
+		t.Errorf("expected a high priority FCM message, got %v", message["android"])
+	}
+	if data := message["data"].(map[string]interface{}); data["url"] != "https://loom.example.com/beads/bead-1" {
+		t.Errorf("unexpected FCM deep link: %v", data)
+	}
+	h := apnsHeaders[0]
+	if h.Get("apns-priority") != "10" || h.Get("apns-topic") != "com.example.loom" || h.Get("apns-collapse-id") != "" ||
+		!strings.HasPrefix(h.Get("Authorization"), "bearer ") {
+		t.Errorf("unexpected APNs headers: %v", h)
+	}
+	if apnsBodies[0]["link"] != "/beads/bead-1" {
+		t.Errorf("unexpected APNs payload: %v", apnsBodies[0])
+	}
+
+	devices, _ := m.Devices("user-admin")
+	if len(devices) != 2 {
+		t.Errorf("expected the unregistered token removed, got %+v", devices)
+	}
+}
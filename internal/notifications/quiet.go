@@ -0,0 +1,143 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuietWindow is a period of the week notifications are held back in,
+// read in the user's time zone. A window whose end is before its start
+// spans midnight and belongs to the day it starts on, so {"days": ["fri"],
+// "start": "22:00", "end": "08:00"} runs from Friday night to Saturday
+// morning. A window without start and end is quiet all day.
+type QuietWindow struct {
+	Days  []string `json:"days,omitempty"`  // mon, tue, wed, thu, fri, sat, sun; empty means every day
+	Start string   `json:"start,omitempty"` // HH:MM
+	End   string   `json:"end,omitempty"`   // HH:MM
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// onDay reports whether the window starts on a weekday
+func (w *QuietWindow) onDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether a wall-clock time falls in the window
+func (w *QuietWindow) contains(t time.Time) bool {
+	if w.Start == "" && w.End == "" {
+		return w.onDay(t.Weekday())
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return w.onDay(t.Weekday()) && now >= start && now < end
+	}
+	// Spans midnight: the evening of a listed day, or the morning after one.
+	// Equal start and end is a whole day from start.
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.onDay(t.Weekday()) && now >= start) || (w.onDay(yesterday) && now < end)
+}
+
+func (w *QuietWindow) validate() error {
+	for _, d := range w.Days {
+		if _, ok := weekdays[d]; !ok {
+			return fmt.Errorf("invalid quiet window day %q (expected mon..sun)", d)
+		}
+	}
+	if w.Start == "" && w.End == "" {
+		if len(w.Days) == 0 {
+			return fmt.Errorf("quiet window needs days, or start and end")
+		}
+		return nil
+	}
+	return validateQuietHours(w.Start, w.End)
+}
+
+func validateQuietSchedule(schedule []*QuietWindow) error {
+	for _, w := range schedule {
+		if w == nil {
+			continue
+		}
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseClock returns the minutes after midnight of an HH:MM time
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// location returns the user's time zone, or the server's when they have
+// not set one
+func (p *NotificationPreferences) location() *time.Location {
+	if p.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// quietAt reports whether a notification of a priority is held back on a
+// channel at an instant, by the channel's quiet windows read in the user's
+// time zone. Critical notifications come through when the user allows it.
+func (p *NotificationPreferences) quietAt(channelPrefs ChannelPreferences, priority string, now time.Time) bool {
+	if priority == PriorityCritical && p.QuietHoursAllowCritical {
+		return false
+	}
+	local := now.In(p.location())
+	for _, w := range channelPrefs.QuietSchedule {
+		if w != nil && w.contains(local) {
+			return true
+		}
+	}
+	return false
+}
+
+// quietSchedule returns the top-level quiet windows: the daily quiet hours,
+// when set, and the weekly schedule
+func (p *NotificationPreferences) quietSchedule() []*QuietWindow {
+	return quietWindows(p.QuietHoursStart, p.QuietHoursEnd, p.QuietSchedule)
+}
+
+func quietWindows(start, end string, schedule []*QuietWindow) []*QuietWindow {
+	var windows []*QuietWindow
+	if start != "" && end != "" {
+		windows = append(windows, &QuietWindow{Start: start, End: end})
+	}
+	return append(windows, schedule...)
+}
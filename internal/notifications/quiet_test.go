@@ -0,0 +1,120 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQuietWindowContains(t *testing.T) {
+	// 2026-10-16 is a Friday
+	at := func(day int, clock string) time.Time {
+		c, _ := time.Parse("15:04", clock)
+		return time.Date(2026, 10, day, c.Hour(), c.Minute(), 0, 0, time.UTC)
+	}
+
+	overnight := &QuietWindow{Days: []string{"fri"}, Start: "22:00", End: "08:00"}
+	weekend := &QuietWindow{Days: []string{"sat", "sun"}}
+	lunch := &QuietWindow{Start: "12:00", End: "13:00"}
+
+	cases := []struct {
+		window *QuietWindow
+		at     time.Time
+		want   bool
+	}{
+		{overnight, at(16, "21:59"), false},
+		{overnight, at(16, "22:00"), true},
+		{overnight, at(17, "07:59"), true},
+		{overnight, at(17, "08:00"), false},
+		{overnight, at(17, "23:00"), false}, // Saturday night is not listed
+		{overnight, at(16, "03:00"), false}, // Friday morning follows Thursday
+		{weekend, at(17, "00:00"), true},
+		{weekend, at(18, "23:59"), true},
+		{weekend, at(19, "00:00"), false},
+		{lunch, at(19, "12:30"), true},
+		{lunch, at(19, "13:00"), false},
+	}
+	for i, c := range cases {
+		if got := c.window.contains(c.at); got != c.want {
+			t.Errorf("case %d: %+v at %s: got %v, want %v", i, c.window, c.at.Format("Mon 15:04"), got, c.want)
+		}
+	}
+}
+
+func TestQuietAt_TimezoneAndCriticalOverride(t *testing.T) {
+	prefs := &NotificationPreferences{
+		EnableInApp:     true,
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "07:00",
+		Timezone:        "America/Los_Angeles",
+	}
+	// 05:30 UTC is 22:30 the evening before in Los Angeles
+	now := time.Date(2026, 10, 16, 5, 30, 0, 0, time.UTC)
+	inApp := prefs.ForChannel(ChannelInApp)
+	if !prefs.quietAt(inApp, PriorityNormal, now) {
+		t.Error("expected quiet hours read in the user's time zone")
+	}
+	if prefs.quietAt(inApp, PriorityNormal, now.Add(-2*time.Hour)) {
+		t.Error("expected 20:30 in Los Angeles to be outside quiet hours")
+	}
+	if !prefs.quietAt(inApp, PriorityCritical, now) {
+		t.Error("expected critical notifications held back unless allowed")
+	}
+	prefs.QuietHoursAllowCritical = true
+	if prefs.quietAt(inApp, PriorityCritical, now) {
+		t.Error("expected critical notifications to come through when allowed")
+	}
+}
+
+func TestForChannel_QuietSchedule(t *testing.T) {
+	prefs := &NotificationPreferences{
+		EnableInApp:     true,
+		QuietHoursStart: "22:00",
+		QuietHoursEnd:   "07:00",
+		QuietSchedule:   []*QuietWindow{{Days: []string{"sat", "sun"}}},
+		Channels: map[string]*ChannelPreferences{
+			ChannelEmail: {Enabled: true, QuietSchedule: []*QuietWindow{{Start: "18:00", End: "09:00"}}},
+		},
+	}
+	if got := prefs.ForChannel(ChannelInApp).QuietSchedule; len(got) != 2 {
+		t.Errorf("expected the daily quiet hours and the weekend, got %+v", got)
+	}
+	email := prefs.ForChannel(ChannelEmail).QuietSchedule
+	if len(email) != 1 || email[0].Start != "18:00" {
+		t.Errorf("expected the channel schedule to replace the top-level one, got %+v", email)
+	}
+}
+
+func TestQuietSchedule_ValidateAndPersist(t *testing.T) {
+	invalid := []*NotificationPreferences{
+		{Timezone: "Mars/Olympus_Mons"},
+		{QuietSchedule: []*QuietWindow{{Days: []string{"funday"}}}},
+		{QuietSchedule: []*QuietWindow{{}}},
+		{QuietSchedule: []*QuietWindow{{Start: "22:00"}}},
+		{Channels: map[string]*ChannelPreferences{ChannelEmail: {QuietSchedule: []*QuietWindow{{Start: "9am", End: "5pm"}}}}},
+	}
+	for i, p := range invalid {
+		if err := p.Validate(); err == nil {
+			t.Errorf("case %d: expected validation error", i)
+		}
+	}
+
+	m := newTestManager(t)
+	prefs, err := m.GetPreferences("user-admin")
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	prefs.Timezone = "Europe/Berlin"
+	prefs.QuietSchedule = []*QuietWindow{{Days: []string{"sat", "sun"}}}
+	prefs.QuietHoursAllowCritical = true
+	if err := m.UpdatePreferences(prefs); err != nil {
+		t.Fatalf("UpdatePreferences failed: %v", err)
+	}
+
+	got, err := m.GetPreferences("user-admin")
+	if err != nil {
+		t.Fatalf("GetPreferences failed: %v", err)
+	}
+	if got.Timezone != "Europe/Berlin" || !got.QuietHoursAllowCritical || len(got.QuietSchedule) != 1 || len(got.QuietSchedule[0].Days) != 2 {
+		t.Errorf("quiet schedule not persisted, got %+v", got)
+	}
+}
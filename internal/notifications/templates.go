@@ -0,0 +1,397 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// Delivery channels
+const (
+	ChannelInApp   = "in_app"
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelPush    = "push"
+)
+
+// Channels lists every supported delivery channel
+var Channels = []string{ChannelInApp, ChannelEmail, ChannelWebhook, ChannelPush}
+
+// NotificationTemplate holds the Go text/template sources used to render a
+// notification for one event type on one channel
+type NotificationTemplate struct {
+	OrgID     string    `json:"org_id"`
+	EventType string    `json:"event_type"`
+	Channel   string    `json:"channel"`
+	Title     string    `json:"title"`
+	Message   string    `json:"message"`
+	Link      string    `json:"link,omitempty"`
+	IsDefault bool      `json:"is_default"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at,omitempty"`
+}
+
+// TemplateData is the value templates are executed against
+type TemplateData struct {
+	UserID        string
+	EventType     string
+	Action        string
+	Source        string
+	ActorID       string
+	ActorType     string
+	ProjectID     string
+	AgentID       string
+	BeadID        string
+	ProviderID    string
+	ResourceType  string
+	ResourceID    string
+	ResourceTitle string
+	Timestamp     time.Time
+	Metadata      map[string]string // activity metadata, formatted as text
+}
+
+// newTemplateData builds template data from an activity
+func newTemplateData(act *activity.Activity, userID string) TemplateData {
+	// Text values let missingkey=zero render absent keys as ""; the zero
+	// value of an interface would print as "<no value>"
+	metadata := make(map[string]string, len(act.Metadata))
+	for k, v := range act.Metadata {
+		if v != nil {
+			metadata[k] = fmt.Sprint(v)
+		}
+	}
+	return TemplateData{
+		UserID:        userID,
+		EventType:     act.EventType,
+		Action:        act.Action,
+		Source:        act.Source,
+		ActorID:       act.ActorID,
+		ActorType:     act.ActorType,
+		ProjectID:     act.ProjectID,
+		AgentID:       act.AgentID,
+		BeadID:        act.BeadID,
+		ProviderID:    act.ProviderID,
+		ResourceType:  act.ResourceType,
+		ResourceID:    act.ResourceID,
+		ResourceTitle: act.ResourceTitle,
+		Timestamp:     act.Timestamp,
+		Metadata:      metadata,
+	}
+}
+
+// defaultTemplates are the built-in templates, shared by all channels
+var defaultTemplates = map[string]NotificationTemplate{
+	"bead.assigned": {
+		Title:   "Bead Assigned to You",
+		Message: "You've been assigned to bead: {{.ResourceTitle}}",
+		Link:    "/beads/{{.ResourceID}}",
+	},
+	"decision.created": {
+		Title:   "Decision Requires Your Input",
+		Message: "A decision needs your attention: {{.ResourceTitle}}",
+		Link:    "/decisions/{{.ResourceID}}",
+	},
+	"bead.created": {
+		Title:   "Critical Bead Created",
+		Message: "A P0 bead was created: {{.ResourceTitle}}",
+		Link:    "/beads/{{.ResourceID}}",
+	},
+	"provider.deleted": {
+		Title:   "System Alert",
+		Message: "{{.Action}}: {{.ResourceTitle}}",
+		Link:    "/{{.ResourceType}}s/{{.ResourceID}}",
+	},
+	"workflow.failed": {
+		Title:   "System Alert",
+		Message: "{{.Action}}: {{.ResourceTitle}}",
+		Link:    "/{{.ResourceType}}s/{{.ResourceID}}",
+	},
+	"golden_prompts.regression": {
+		Title:   "Golden Prompt Regression",
+		Message: "{{.ResourceTitle}}",
+		Link:    "/api/v1/projects/{{.ProjectID}}/golden-prompts/runs/{{.ResourceID}}/report?format=html",
+	},
+}
+
+// DefaultTemplate returns the built-in template for an event type and channel
+func DefaultTemplate(eventType, channel string) (NotificationTemplate, bool) {
+	tmpl, ok := defaultTemplates[eventType]
+	if !ok {
+		return NotificationTemplate{}, false
+	}
+	tmpl.EventType = eventType
+	tmpl.Channel = channel
+	tmpl.IsDefault = true
+	return tmpl, true
+}
+
+// TemplateEventTypes returns the event types that have built-in templates
+func TemplateEventTypes() []string {
+	types := make([]string, 0, len(defaultTemplates))
+	for eventType := range defaultTemplates {
+		types = append(types, eventType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// isValidChannel reports whether channel is a supported delivery channel
+func isValidChannel(channel string) bool {
+	for _, c := range Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleTemplateData is used to validate templates before they are saved
+var sampleTemplateData = TemplateData{
+	UserID:        "user-sample",
+	EventType:     "bead.created",
+	Action:        "created",
+	Source:        "loom",
+	ActorID:       "agent-sample",
+	ActorType:     "agent",
+	ProjectID:     "project-sample",
+	AgentID:       "agent-sample",
+	BeadID:        "bead-sample",
+	ProviderID:    "provider-sample",
+	ResourceType:  "bead",
+	ResourceID:    "bead-sample",
+	ResourceTitle: "Sample bead",
+	Timestamp:     time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+	Metadata:      map[string]string{"priority": "P0"},
+}
+
+// ValidateTemplate checks that a template has a supported channel, parses,
+// and renders a non-empty title and message against sample data
+func ValidateTemplate(tmpl *NotificationTemplate) error {
+	if tmpl.EventType == "" {
+		return fmt.Errorf("event_type is required")
+	}
+	if !isValidChannel(tmpl.Channel) {
+		return fmt.Errorf("invalid channel %q (must be one of %s)", tmpl.Channel, strings.Join(Channels, ", "))
+	}
+	if strings.TrimSpace(tmpl.Title) == "" {
+		return fmt.Errorf("title template is required")
+	}
+	if strings.TrimSpace(tmpl.Message) == "" {
+		return fmt.Errorf("message template is required")
+	}
+
+	title, message, _, err := renderTemplate(tmpl, sampleTemplateData)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(title) == "" {
+		return fmt.Errorf("title template renders to an empty string")
+	}
+	if strings.TrimSpace(message) == "" {
+		return fmt.Errorf("message template renders to an empty string")
+	}
+	return nil
+}
+
+// renderTemplate executes the title, message and link templates
+func renderTemplate(tmpl *NotificationTemplate, data TemplateData) (title, message, link string, err error) {
+	if title, err = executeTemplate("title", tmpl.Title, data); err != nil {
+		return "", "", "", err
+	}
+	if message, err = executeTemplate("message", tmpl.Message, data); err != nil {
+		return "", "", "", err
+	}
+	if link, err = executeTemplate("link", tmpl.Link, data); err != nil {
+		return "", "", "", err
+	}
+	return title, message, link, nil
+}
+
+func executeTemplate(name, text string, data TemplateData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+	t, err := template.New(name).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s template: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s template: %w", name, err)
+	}
+	return buf.String(), nil
+}
+
+// renderNotification renders the notification text for an activity on a
+// channel, preferring the org's template and falling back to the default
+func (m *Manager) renderNotification(orgID string, act *activity.Activity, userID, channel string) (title, message, link string) {
+	data := newTemplateData(act, userID)
+
+	if override, err := m.getTemplateOverride(orgID, act.EventType, channel); err != nil {
+		log.Printf("Failed to load notification template for %s/%s: %v", act.EventType, channel, err)
+	} else if override != nil {
+		title, message, link, err = renderTemplate(override, data)
+		if err == nil && title != "" {
+			return title, message, link
+		}
+		if err != nil {
+			log.Printf("Notification template for %s/%s failed, using default: %v", act.EventType, channel, err)
+		}
+	}
+
+	def, ok := DefaultTemplate(act.EventType, channel)
+	if !ok {
+		return "", "", ""
+	}
+	title, message, link, err := renderTemplate(&def, data)
+	if err != nil {
+		log.Printf("Default notification template for %s failed: %v", act.EventType, err)
+		return "", "", ""
+	}
+	return title, message, link
+}
+
+// getTemplateOverride loads the org's template, falling back to the default org
+func (m *Manager) getTemplateOverride(orgID, eventType, channel string) (*NotificationTemplate, error) {
+	if m.db == nil {
+		return nil, nil
+	}
+	if orgID == "" {
+		orgID = org.DefaultID
+	}
+
+	dbTmpl, err := m.db.GetNotificationTemplate(orgID, eventType, channel)
+	if err != nil {
+		return nil, err
+	}
+	if dbTmpl == nil && orgID != org.DefaultID {
+		dbTmpl, err = m.db.GetNotificationTemplate(org.DefaultID, eventType, channel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if dbTmpl == nil {
+		return nil, nil
+	}
+	return fromDBTemplate(dbTmpl), nil
+}
+
+// GetTemplate returns the effective template for an org, event type and channel
+func (m *Manager) GetTemplate(orgID, eventType, channel string) (*NotificationTemplate, error) {
+	if !isValidChannel(channel) {
+		return nil, fmt.Errorf("invalid channel %q", channel)
+	}
+	override, err := m.getTemplateOverride(orgID, eventType, channel)
+	if err != nil {
+		return nil, err
+	}
+	if override != nil {
+		return override, nil
+	}
+	def, ok := DefaultTemplate(eventType, channel)
+	if !ok {
+		return nil, fmt.Errorf("no template for event type %q", eventType)
+	}
+	def.OrgID = orgID
+	return &def, nil
+}
+
+// ListTemplates returns the effective templates for every built-in event
+// type and channel, plus any overrides for other event types
+func (m *Manager) ListTemplates(orgID string) ([]*NotificationTemplate, error) {
+	if orgID == "" {
+		orgID = org.DefaultID
+	}
+
+	overrides := make(map[string]*NotificationTemplate)
+	dbTemplates, err := m.db.ListNotificationTemplates(orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range dbTemplates {
+		overrides[t.EventType+"/"+t.Channel] = fromDBTemplate(t)
+	}
+
+	var result []*NotificationTemplate
+	for _, eventType := range TemplateEventTypes() {
+		for _, channel := range Channels {
+			key := eventType + "/" + channel
+			if override, ok := overrides[key]; ok {
+				result = append(result, override)
+				delete(overrides, key)
+				continue
+			}
+			def, _ := DefaultTemplate(eventType, channel)
+			def.OrgID = orgID
+			result = append(result, &def)
+		}
+	}
+
+	remaining := make([]*NotificationTemplate, 0, len(overrides))
+	for _, t := range overrides {
+		remaining = append(remaining, t)
+	}
+	sort.Slice(remaining, func(i, j int) bool {
+		if remaining[i].EventType != remaining[j].EventType {
+			return remaining[i].EventType < remaining[j].EventType
+		}
+		return remaining[i].Channel < remaining[j].Channel
+	})
+
+	return append(result, remaining...), nil
+}
+
+// SetTemplate validates and stores a template override
+func (m *Manager) SetTemplate(tmpl *NotificationTemplate) error {
+	if tmpl.OrgID == "" {
+		tmpl.OrgID = org.DefaultID
+	}
+	if err := ValidateTemplate(tmpl); err != nil {
+		return err
+	}
+
+	tmpl.IsDefault = false
+	tmpl.UpdatedAt = time.Now()
+
+	return m.db.UpsertNotificationTemplate(&database.NotificationTemplate{
+		ID:              uuid.New().String(),
+		OrgID:           tmpl.OrgID,
+		EventType:       tmpl.EventType,
+		Channel:         tmpl.Channel,
+		TitleTemplate:   tmpl.Title,
+		MessageTemplate: tmpl.Message,
+		LinkTemplate:    tmpl.Link,
+		UpdatedBy:       tmpl.UpdatedBy,
+		UpdatedAt:       tmpl.UpdatedAt,
+	})
+}
+
+// DeleteTemplate removes a template override, reverting to the default
+func (m *Manager) DeleteTemplate(orgID, eventType, channel string) error {
+	if orgID == "" {
+		orgID = org.DefaultID
+	}
+	return m.db.DeleteNotificationTemplate(orgID, eventType, channel)
+}
+
+func fromDBTemplate(t *database.NotificationTemplate) *NotificationTemplate {
+	return &NotificationTemplate{
+		OrgID:     t.OrgID,
+		EventType: t.EventType,
+		Channel:   t.Channel,
+		Title:     t.TitleTemplate,
+		Message:   t.MessageTemplate,
+		Link:      t.LinkTemplate,
+		UpdatedBy: t.UpdatedBy,
+		UpdatedAt: t.UpdatedAt,
+	}
+}
@@ -0,0 +1,179 @@
+package notifications
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return &Manager{db: db, subscribers: make(map[string]map[string]chan *Notification)}
+}
+
+func TestValidateTemplate(t *testing.T) {
+	tests := []struct {
+		name    string
+		tmpl    NotificationTemplate
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			tmpl: NotificationTemplate{EventType: "bead.assigned", Channel: ChannelEmail, Title: "Hi {{.UserID}}", Message: "{{.ResourceTitle}}"},
+		},
+		{
+			name:    "invalid channel",
+			tmpl:    NotificationTemplate{EventType: "bead.assigned", Channel: "pager", Title: "t", Message: "m"},
+			wantErr: true,
+		},
+		{
+			name:    "parse error",
+			tmpl:    NotificationTemplate{EventType: "bead.assigned", Channel: ChannelInApp, Title: "{{.ResourceTitle", Message: "m"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown field",
+			tmpl:    NotificationTemplate{EventType: "bead.assigned", Channel: ChannelInApp, Title: "{{.Nope}}", Message: "m"},
+			wantErr: true,
+		},
+		{
+			name:    "renders empty",
+			tmpl:    NotificationTemplate{EventType: "bead.assigned", Channel: ChannelInApp, Title: "{{.Metadata.missing}}", Message: "m"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplate(&tt.tmpl)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatNotification_Defaults(t *testing.T) {
+	m := newTestManager(t)
+
+	act := &activity.Activity{
+		EventType:     "bead.assigned",
+		ResourceID:    "bead-1",
+		ResourceTitle: "Fix login",
+		Metadata:      map[string]interface{}{"assigned_to": "user-1"},
+	}
+
+	title, message, link := m.formatNotification(act, "user-1")
+	if title != "Bead Assigned to You" {
+		t.Errorf("title = %q", title)
+	}
+	if message != "You've been assigned to bead: Fix login" {
+		t.Errorf("message = %q", message)
+	}
+	if link != "/beads/bead-1" {
+		t.Errorf("link = %q", link)
+	}
+
+	if title, _, _ := m.formatNotification(act, "user-2"); title != "" {
+		t.Errorf("expected no notification for non-assignee, got %q", title)
+	}
+}
+
+func TestFormatNotification_Override(t *testing.T) {
+	m := newTestManager(t)
+
+	err := m.SetTemplate(&NotificationTemplate{
+		EventType: "workflow.failed",
+		Channel:   ChannelInApp,
+		Title:     "Workflow {{.ResourceTitle}} failed",
+		Message:   "Project {{.ProjectID}}: {{.Action}}",
+	})
+	if err != nil {
+		t.Fatalf("SetTemplate failed: %v", err)
+	}
+
+	act := &activity.Activity{
+		EventType:     "workflow.failed",
+		Action:        "failed",
+		ProjectID:     "proj-1",
+		ResourceType:  "workflow",
+		ResourceID:    "wf-1",
+		ResourceTitle: "nightly",
+	}
+
+	title, message, link := m.formatNotification(act, "user-1")
+	if title != "Workflow nightly failed" || message != "Project proj-1: failed" || link != "" {
+		t.Errorf("unexpected rendering: %q / %q / %q", title, message, link)
+	}
+
+	// Email channel has no override and falls back to the default
+	title, _, _ = m.formatNotificationForChannel(act, "user-1", ChannelEmail)
+	if title != "System Alert" {
+		t.Errorf("email title = %q, want default", title)
+	}
+
+	if err := m.DeleteTemplate(org.DefaultID, "workflow.failed", ChannelInApp); err != nil {
+		t.Fatalf("DeleteTemplate failed: %v", err)
+	}
+	if title, _, _ := m.formatNotification(act, "user-1"); title != "System Alert" {
+		t.Errorf("title after delete = %q, want default", title)
+	}
+}
+
+func TestFormatNotification_OrgOverride(t *testing.T) {
+	m := newTestManager(t)
+
+	err := m.SetTemplate(&NotificationTemplate{
+		OrgID:     "acme",
+		EventType: "workflow.failed",
+		Channel:   ChannelInApp,
+		Title:     "Acme: {{.ResourceTitle}} failed{{.Metadata.missing}}",
+		Message:   "Retry {{.Metadata.attempt}}",
+	})
+	if err != nil {
+		t.Fatalf("SetTemplate failed: %v", err)
+	}
+
+	act := &activity.Activity{
+		EventType:     "workflow.failed",
+		OrgID:         "acme",
+		ResourceTitle: "nightly",
+		Metadata:      map[string]interface{}{"attempt": 3},
+	}
+	title, message, _ := m.formatNotification(act, "user-1")
+	if title != "Acme: nightly failed" || message != "Retry 3" {
+		t.Errorf("unexpected rendering: %q / %q", title, message)
+	}
+
+	// Other organizations keep the default
+	act.OrgID = "globex"
+	if title, _, _ := m.formatNotification(act, "user-1"); title != "System Alert" {
+		t.Errorf("title for another org = %q, want default", title)
+	}
+}
+
+func TestListTemplates(t *testing.T) {
+	m := newTestManager(t)
+
+	templates, err := m.ListTemplates("")
+	if err != nil {
+		t.Fatalf("ListTemplates failed: %v", err)
+	}
+	want := len(TemplateEventTypes()) * len(Channels)
+	if len(templates) != want {
+		t.Fatalf("got %d templates, want %d", len(templates), want)
+	}
+	for _, tmpl := range templates {
+		if !tmpl.IsDefault {
+			t.Errorf("expected default template for %s/%s", tmpl.EventType, tmpl.Channel)
+		}
+	}
+}
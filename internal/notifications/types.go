@@ -6,35 +6,54 @@ import (
 
 // Notification represents a user notification
 type Notification struct {
-	ID         string                 `json:"id"`
-	UserID     string                 `json:"user_id"`
-	ActivityID string                 `json:"activity_id,omitempty"`
-	EventType  string                 `json:"event_type"`
-	Title      string                 `json:"title"`
-	Message    string                 `json:"message"`
-	Link       string                 `json:"link,omitempty"`
-	Status     string                 `json:"status"`
-	Priority   string                 `json:"priority"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
-	CreatedAt  time.Time              `json:"created_at"`
-	ReadAt     *time.Time             `json:"read_at,omitempty"`
-	ArchivedAt *time.Time             `json:"archived_at,omitempty"`
+	ID           string                 `json:"id"`
+	UserID       string                 `json:"user_id"`
+	ActivityID   string                 `json:"activity_id,omitempty"`
+	ProjectID    string                 `json:"project_id,omitempty"`
+	EventType    string                 `json:"event_type"`
+	Title        string                 `json:"title"`
+	Message      string                 `json:"message"`
+	Link         string                 `json:"link,omitempty"`
+	Status       string                 `json:"status"`
+	Priority     string                 `json:"priority"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	ReadAt       *time.Time             `json:"read_at,omitempty"`
+	ArchivedAt   *time.Time             `json:"archived_at,omitempty"`
+	SnoozedUntil *time.Time             `json:"snoozed_until,omitempty"` // When a snoozed notification becomes unread again
 }
 
 // NotificationPreferences represents user notification preferences
 type NotificationPreferences struct {
-	ID               string    `json:"id"`
-	UserID           string    `json:"user_id"`
-	EnableInApp      bool      `json:"enable_in_app"`
-	EnableEmail      bool      `json:"enable_email"`
-	EnableWebhook    bool      `json:"enable_webhook"`
-	SubscribedEvents []string  `json:"subscribed_events"`
-	DigestMode       string    `json:"digest_mode"`
-	QuietHoursStart  string    `json:"quiet_hours_start,omitempty"`
-	QuietHoursEnd    string    `json:"quiet_hours_end,omitempty"`
-	ProjectFilters   []string  `json:"project_filters,omitempty"`
-	MinPriority      string    `json:"min_priority"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID                      string                         `json:"id"`
+	UserID                  string                         `json:"user_id"`
+	EnableInApp             bool                           `json:"enable_in_app"`
+	EnableEmail             bool                           `json:"enable_email"`
+	EnableWebhook           bool                           `json:"enable_webhook"`
+	EnablePush              bool                           `json:"enable_push"`
+	SubscribedEvents        []string                       `json:"subscribed_events"`
+	DigestMode              string                         `json:"digest_mode"`
+	QuietHoursStart         string                         `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd           string                         `json:"quiet_hours_end,omitempty"`
+	QuietSchedule           []*QuietWindow                 `json:"quiet_schedule,omitempty"`   // Weekly quiet windows, alongside the daily quiet hours
+	Timezone                string                         `json:"timezone,omitempty"`         // IANA time zone quiet hours are read in; default the server's
+	QuietHoursAllowCritical bool                           `json:"quiet_hours_allow_critical"` // Critical notifications come through quiet hours
+	ProjectFilters          []string                       `json:"project_filters,omitempty"`
+	MinPriority             string                         `json:"min_priority"`
+	Channels                map[string]*ChannelPreferences `json:"channels,omitempty"` // Per-channel overrides keyed by channel name
+	MuteRules               []*MuteRule                    `json:"mute_rules,omitempty"`
+	UpdatedAt               time.Time                      `json:"updated_at"`
+}
+
+// ChannelPreferences overrides the user's notification settings for a single
+// delivery channel. Empty fields inherit the top-level preference.
+type ChannelPreferences struct {
+	Enabled          bool           `json:"enabled"`
+	MinPriority      string         `json:"min_priority,omitempty"`
+	SubscribedEvents []string       `json:"subscribed_events,omitempty"`
+	QuietHoursStart  string         `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd    string         `json:"quiet_hours_end,omitempty"`
+	QuietSchedule    []*QuietWindow `json:"quiet_schedule,omitempty"`
 }
 
 // Priority levels
@@ -50,6 +69,7 @@ const (
 	StatusUnread   = "unread"
 	StatusRead     = "read"
 	StatusArchived = "archived"
+	StatusSnoozed  = "snoozed"
 )
 
 // Digest modes
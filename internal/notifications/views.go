@@ -0,0 +1,110 @@
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// ViewSubscription notifies a user of the activities a saved activity view
+// matches, from MinPriority up, whether or not the default rules would
+// notify them
+type ViewSubscription struct {
+	ViewID      string    `json:"view_id"`
+	UserID      string    `json:"user_id"`
+	MinPriority string    `json:"min_priority"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	view *activity.View
+}
+
+// SubscribeView subscribes a user to a view, or changes the priority an
+// existing subscription notifies from
+func (m *Manager) SubscribeView(viewID, userID, minPriority string) (*ViewSubscription, error) {
+	if err := validatePriority(minPriority); err != nil {
+		return nil, err
+	}
+	if minPriority == "" {
+		minPriority = PriorityLow
+	}
+	sub := &ViewSubscription{ViewID: viewID, UserID: userID, MinPriority: minPriority, CreatedAt: time.Now().UTC()}
+	err := m.db.SaveActivityViewSubscription(&database.ActivityViewSubscription{
+		ViewID:      sub.ViewID,
+		UserID:      sub.UserID,
+		MinPriority: sub.MinPriority,
+		CreatedAt:   sub.CreatedAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+// UnsubscribeView unsubscribes a user from a view
+func (m *Manager) UnsubscribeView(viewID, userID string) error {
+	return m.db.DeleteActivityViewSubscription(viewID, userID)
+}
+
+// ViewSubscriptions returns a user's view subscriptions
+func (m *Manager) ViewSubscriptions(userID string) ([]*ViewSubscription, error) {
+	return m.viewSubscriptions(database.ActivityViewSubscriptionFilters{UserID: userID})
+}
+
+func (m *Manager) viewSubscriptions(f database.ActivityViewSubscriptionFilters) ([]*ViewSubscription, error) {
+	dbSubs, err := m.db.ListActivityViewSubscriptions(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list view subscriptions: %w", err)
+	}
+	subs := make([]*ViewSubscription, 0, len(dbSubs))
+	for _, s := range dbSubs {
+		subs = append(subs, &ViewSubscription{
+			ViewID:      s.ViewID,
+			UserID:      s.UserID,
+			MinPriority: s.MinPriority,
+			CreatedAt:   s.CreatedAt,
+			view:        activity.FromDBView(s.View),
+		})
+	}
+	return subs, nil
+}
+
+// subscribedUsers returns the users a view subscription notifies of an
+// activity of organization orgID
+func (m *Manager) subscribedUsers(act *activity.Activity, orgID string) (map[string]bool, error) {
+	subs, err := m.viewSubscriptions(database.ActivityViewSubscriptionFilters{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+	priority := m.determinePriority(act)
+	users := make(map[string]bool)
+	for _, sub := range subs {
+		if sub.view.Filters.Matches(act) && m.meetsPriorityThreshold(priority, sub.MinPriority) {
+			users[sub.UserID] = true
+		}
+	}
+	return users, nil
+}
+
+// viewTemplate is the text of view notifications of events with no
+// template of their own
+var viewTemplate = NotificationTemplate{
+	Title:   "Activity: {{.ResourceType}} {{.Action}}",
+	Message: "{{.ResourceTitle}}",
+	Link:    "/{{.ResourceType}}s/{{.ResourceID}}",
+}
+
+// renderViewNotification renders a view notification with the org's
+// template for the event, or viewTemplate when there is none
+func (m *Manager) renderViewNotification(act *activity.Activity, userID, channel string) (title, message, link string) {
+	if title, message, link = m.renderNotification(org.Normalize(act.OrgID), act, userID, channel); title != "" {
+		return title, message, link
+	}
+	title, message, link, err := renderTemplate(&viewTemplate, newTemplateData(act, userID))
+	if err != nil {
+		return "", "", ""
+	}
+	return title, message, link
+}
@@ -0,0 +1,74 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/activity"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+func TestProcessActivity_ViewSubscription(t *testing.T) {
+	m := newTestManager(t)
+	views := activity.NewManager(m.db, nil)
+	view := &activity.View{
+		OrgID:   org.DefaultID,
+		OwnerID: "user-admin",
+		Name:    "My projects",
+		Filters: activity.ViewFilters{ProjectIDs: []string{"proj-1"}, EventTypes: []string{"bead.status_change"}},
+	}
+	if err := views.CreateView(view); err != nil {
+		t.Fatalf("CreateView failed: %v", err)
+	}
+	if _, err := m.SubscribeView(view.ID, "user-admin", "urgent"); err == nil {
+		t.Error("expected an invalid priority to be refused")
+	}
+	if _, err := m.SubscribeView(view.ID, "user-admin", PriorityHigh); err != nil {
+		t.Fatalf("SubscribeView failed: %v", err)
+	}
+
+	changed := func(projectID, priority string) *activity.Activity {
+		return &activity.Activity{
+			EventType:     "bead.status_change",
+			Action:        "status_change",
+			ProjectID:     projectID,
+			ResourceType:  "bead",
+			ResourceID:    "bead-1",
+			ResourceTitle: "Fix login",
+			Visibility:    "project",
+			Metadata:      map[string]interface{}{"priority": priority},
+		}
+	}
+	// The default rules notify no one of status changes; the view
+	// subscription notifies of its project's P0 and P1 beads alone
+	for _, a := range []*activity.Activity{
+		changed("proj-1", "P1"),
+		changed("proj-1", "P0"),
+		changed("proj-1", "P2"),
+		changed("proj-2", "P0"),
+	} {
+		if err := m.ProcessActivity(a); err != nil {
+			t.Fatalf("ProcessActivity failed: %v", err)
+		}
+	}
+	stored, err := m.GetNotifications("user-admin", "", 10, 0)
+	if err != nil {
+		t.Fatalf("GetNotifications failed: %v", err)
+	}
+	if len(stored) != 2 {
+		t.Fatalf("expected 2 notifications, got %d", len(stored))
+	}
+
+	subs, err := m.ViewSubscriptions("user-admin")
+	if err != nil || len(subs) != 1 || subs[0].MinPriority != PriorityHigh {
+		t.Fatalf("ViewSubscriptions = %+v, %v", subs, err)
+	}
+	if err := m.UnsubscribeView(view.ID, "user-admin"); err != nil {
+		t.Fatalf("UnsubscribeView failed: %v", err)
+	}
+	if err := m.ProcessActivity(changed("proj-1", "P0")); err != nil {
+		t.Fatalf("ProcessActivity failed: %v", err)
+	}
+	if stored, _ := m.GetNotifications("user-admin", "", 10, 0); len(stored) != 2 {
+		t.Errorf("expected no notifications once unsubscribed, got %d", len(stored))
+	}
+}
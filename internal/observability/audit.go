@@ -0,0 +1,90 @@
+package observability
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/audit"
+)
+
+var (
+	securityAuditMu   sync.Mutex
+	securityAuditPath string
+)
+
+// SetSecurityAuditLog sets the JSONL file that security audit events are
+// appended to. An empty path disables the file sink; events are still
+// emitted to the process log.
+func SetSecurityAuditLog(path string) error {
+	securityAuditMu.Lock()
+	defer securityAuditMu.Unlock()
+
+	if path != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+	}
+	securityAuditPath = path
+	return nil
+}
+
+// SecurityAudit records a security-relevant event (credential changes,
+// permission changes, etc.) to the process log and the security audit log.
+// Callers must never include secret material in fields.
+func SecurityAudit(event string, fields map[string]interface{}) {
+	payload := cloneFields(fields)
+	payload["audit"] = "security"
+	logEvent("info", event, payload)
+	audit.Record(audit.Event{
+		Actor:     securityAuditActor(fields),
+		Action:    event,
+		ProjectID: stringField(fields, "project_id"),
+		Outcome:   securityAuditOutcome(fields),
+		Details:   cloneFields(fields),
+	})
+
+	securityAuditMu.Lock()
+	defer securityAuditMu.Unlock()
+	if securityAuditPath == "" {
+		return
+	}
+
+	payload["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
+	payload["event"] = event
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	f, err := os.OpenFile(securityAuditPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.Write(append(raw, '\n'))
+}
+
+// securityAuditActor picks the acting principal from the conventional
+// security audit fields
+func securityAuditActor(fields map[string]interface{}) string {
+	for _, key := range []string{"actor", "user_id", "initiated_by", "approved_by", "agent_id"} {
+		if v := stringField(fields, key); v != "" {
+			return v
+		}
+	}
+	return "system"
+}
+
+func securityAuditOutcome(fields map[string]interface{}) string {
+	if stringField(fields, "error") != "" {
+		return audit.OutcomeFailure
+	}
+	return audit.OutcomeSuccess
+}
+
+func stringField(fields map[string]interface{}, key string) string {
+	s, _ := fields[key].(string)
+	return s
+}
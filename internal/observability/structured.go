@@ -1,9 +1,12 @@
 package observability
 
 import (
-	"encoding/json"
-	"log"
-	"time"
+	"context"
+	"log/slog"
+	"sort"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/logging"
 )
 
 func Info(event string, fields map[string]interface{}) {
@@ -18,27 +21,28 @@ func Error(event string, fields map[string]interface{}, err error) {
 	logEvent("error", event, payload)
 }
 
+// logEvent writes an event to the structured log of the module its name
+// starts with, so "git.push.start" follows the git module's level
 func logEvent(level, event string, fields map[string]interface{}) {
-	payload := cloneFields(fields)
-	payload["ts"] = time.Now().UTC().Format(time.RFC3339Nano)
-	payload["level"] = level
-	payload["event"] = event
-	raw, err := json.Marshal(payload)
-	if err != nil {
-		fallback := map[string]interface{}{
-			"ts":    time.Now().UTC().Format(time.RFC3339Nano),
-			"level": "error",
-			"event": "log.marshal_failed",
-			"error": err.Error(),
-		}
-		if fields != nil {
-			fallback["event_payload"] = fields
-		}
-		fallbackRaw, _ := json.Marshal(fallback)
-		log.Print(string(fallbackRaw))
-		return
+	module := event
+	if i := strings.Index(event, "."); i > 0 {
+		module = event[:i]
+	}
+	lvl := slog.LevelInfo
+	if level == "error" {
+		lvl = slog.LevelError
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]any, 0, 2*len(keys))
+	for _, k := range keys {
+		args = append(args, k, fields[k])
 	}
-	log.Print(string(raw))
+	logging.Logger(module).Log(context.Background(), lvl, event, args...)
 }
 
 func cloneFields(fields map[string]interface{}) map[string]interface{} {
@@ -0,0 +1,149 @@
+package onboarding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultDependencyTimeout bounds each package manager query, which has to
+// reach the network
+const DefaultDependencyTimeout = 2 * time.Minute
+
+// CommandDependencyChecker asks the repository's package managers for
+// newer releases of its direct dependencies: go list for Go modules and
+// npm outdated for npm packages
+type CommandDependencyChecker struct {
+	Timeout time.Duration
+}
+
+// Outdated lists the direct dependencies with newer releases. Ecosystems
+// whose package manager is missing or fails are reported in the error,
+// after the others have been checked.
+func (c CommandDependencyChecker) Outdated(ctx context.Context, dir string) ([]Dependency, error) {
+	var deps []Dependency
+	var errs []error
+	if fileExists(filepath.Join(dir, "go.mod")) {
+		found, err := c.goOutdated(ctx, dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("go: %w", err))
+		}
+		deps = append(deps, found...)
+	}
+	if fileExists(filepath.Join(dir, "package.json")) {
+		found, err := c.npmOutdated(ctx, dir)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("npm: %w", err))
+		}
+		deps = append(deps, found...)
+	}
+	return deps, errors.Join(errs...)
+}
+
+func (c CommandDependencyChecker) goOutdated(ctx context.Context, dir string) ([]Dependency, error) {
+	out, err := c.run(ctx, dir, "go", "list", "-m", "-u", "-json", "all")
+	if err != nil {
+		return nil, err
+	}
+	return parseGoModules(strings.NewReader(out))
+}
+
+// parseGoModules reads the stream of go list -m -u -json, keeping direct
+// dependencies with an update
+func parseGoModules(r io.Reader) ([]Dependency, error) {
+	var deps []Dependency
+	dec := json.NewDecoder(r)
+	for {
+		var m struct {
+			Path     string
+			Version  string
+			Main     bool
+			Indirect bool
+			Update   *struct{ Version string }
+		}
+		if err := dec.Decode(&m); err == io.EOF {
+			return deps, nil
+		} else if err != nil {
+			return deps, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		if m.Main || m.Indirect || m.Update == nil {
+			continue
+		}
+		deps = append(deps, Dependency{Ecosystem: "go", Name: m.Path, Current: m.Version, Latest: m.Update.Version})
+	}
+}
+
+func (c CommandDependencyChecker) npmOutdated(ctx context.Context, dir string) ([]Dependency, error) {
+	// npm outdated exits 1 when anything is outdated, so its output is
+	// parsed whatever the exit status
+	out, err := c.run(ctx, dir, "npm", "outdated", "--json")
+	if strings.TrimSpace(out) == "" {
+		return nil, err
+	}
+	return parseNpmOutdated([]byte(out))
+}
+
+// parseNpmOutdated reads the output of npm outdated --json
+func parseNpmOutdated(data []byte) ([]Dependency, error) {
+	var packages map[string]struct {
+		Current string `json:"current"`
+		Latest  string `json:"latest"`
+	}
+	if err := json.Unmarshal(data, &packages); err != nil {
+		return nil, fmt.Errorf("failed to parse npm outdated output: %w", err)
+	}
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var deps []Dependency
+	for _, name := range names {
+		p := packages[name]
+		if p.Latest == "" || p.Current == p.Latest {
+			continue
+		}
+		current := p.Current
+		if current == "" {
+			current = "not installed"
+		}
+		deps = append(deps, Dependency{Ecosystem: "npm", Name: name, Current: current, Latest: p.Latest})
+	}
+	return deps, nil
+}
+
+func (c CommandDependencyChecker) run(ctx context.Context, dir, name string, args ...string) (string, error) {
+	if _, err := exec.LookPath(name); err != nil {
+		return "", fmt.Errorf("%s not found", name)
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultDependencyTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return string(out), fmt.Errorf("%s %s: %w: %s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return string(out), nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,512 @@
+// Package onboarding scans a newly added repository for work it already
+// needs (packages without tests, TODO and FIXME comments, outdated
+// dependencies and lint violations) and proposes a prioritized backlog of
+// beads for it, each with a complexity estimate. Filing the beads is left
+// to the caller, which owns projects and beads.
+package onboarding
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/linter"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Kinds of finding a proposed bead addresses
+const (
+	KindMissingTests       = "missing_tests"
+	KindTodo               = "todo"
+	KindOutdatedDependency = "outdated_dependency"
+	KindLint               = "lint"
+)
+
+const (
+	// DefaultMaxBeads caps the backlog a scan proposes
+	DefaultMaxBeads = 30
+	// maxFileSize skips files too large to be hand-written source
+	maxFileSize = 1 << 20
+	// maxListed caps the locations listed in one bead's description
+	maxListed = 20
+)
+
+// skipDirs are never scanned
+var skipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, "__pycache__": true, ".venv": true, "venv": true, ".beads": true,
+	".idea": true, ".vscode": true, "coverage": true, "testdata": true,
+}
+
+// sourceExts are the file extensions scanned for comments
+var sourceExts = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".jsx": true, ".ts": true, ".tsx": true,
+	".rb": true, ".java": true, ".kt": true, ".rs": true, ".c": true, ".h": true,
+	".cc": true, ".cpp": true, ".hpp": true, ".cs": true, ".php": true, ".swift": true,
+	".scala": true, ".sh": true,
+}
+
+// todoPattern matches a TODO-style marker inside a comment
+var todoPattern = regexp.MustCompile(`(?://|#|/\*|^\s*\*|--)\s*.*?\b(TODO|FIXME|XXX|HACK|BUG)\b[:(]?\s*(.*)`)
+
+// Linter runs the project's linter. linter.LinterRunner satisfies it.
+type Linter interface {
+	Run(ctx context.Context, req linter.LintRequest) (*linter.LintResult, error)
+}
+
+// Dependency is a direct dependency with a newer release
+type Dependency struct {
+	Ecosystem string `json:"ecosystem"` // "go" or "npm"
+	Name      string `json:"name"`
+	Current   string `json:"current"`
+	Latest    string `json:"latest"`
+}
+
+// Major reports whether the newer release changes the major version
+func (d Dependency) Major() bool {
+	return majorVersion(d.Current) != majorVersion(d.Latest)
+}
+
+// DependencyChecker lists a repository's outdated direct dependencies
+type DependencyChecker interface {
+	Outdated(ctx context.Context, dir string) ([]Dependency, error)
+}
+
+// ProposedBead is a bead a scan suggests filing
+type ProposedBead struct {
+	Title       string              `json:"title"`
+	Description string              `json:"description"`
+	Priority    models.BeadPriority `json:"priority"`
+	Type        string              `json:"type"`
+	Kind        string              `json:"kind"`
+	Complexity  string              `json:"complexity"` // simple, medium, complex or extended
+	Files       []string            `json:"files,omitempty"`
+	BeadID      string              `json:"bead_id,omitempty"` // Set once the bead is filed
+}
+
+// Report is the outcome of a scan
+type Report struct {
+	ProjectID string         `json:"project_id,omitempty"`
+	ScannedAt time.Time      `json:"scanned_at"`
+	Files     int            `json:"files"`              // Source files scanned
+	Findings  map[string]int `json:"findings"`           // Kind -> findings behind the proposed beads
+	Beads     []ProposedBead `json:"beads"`              // Highest priority first, quick wins first within a priority
+	Omitted   int            `json:"omitted"`            // Proposals left out by the cap
+	Warnings  []string       `json:"warnings,omitempty"` // Checks that could not run
+	Filed     bool           `json:"filed"`
+}
+
+// Scanner analyzes repositories
+type Scanner struct {
+	linter    Linter
+	deps      DependencyChecker
+	estimator *provider.ComplexityEstimator
+}
+
+// NewScanner creates a scanner. A nil linter or dependency checker skips
+// that check.
+func NewScanner(lint Linter, deps DependencyChecker) *Scanner {
+	return &Scanner{linter: lint, deps: deps, estimator: provider.NewComplexityEstimator()}
+}
+
+// Scan analyzes the repository checked out in dir and proposes at most
+// maxBeads beads, or DefaultMaxBeads when maxBeads is not positive
+func (s *Scanner) Scan(ctx context.Context, dir string, maxBeads int) (*Report, error) {
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("repository directory %s not found", dir)
+	}
+	if maxBeads <= 0 {
+		maxBeads = DefaultMaxBeads
+	}
+
+	report := &Report{ScannedAt: time.Now().UTC(), Findings: map[string]int{}}
+	tree, err := walkSources(ctx, dir)
+	if err != nil {
+		return nil, err
+	}
+	report.Files = len(tree.files)
+
+	var beads []ProposedBead
+	beads = append(beads, s.missingTests(tree, report)...)
+	beads = append(beads, s.todos(tree, report)...)
+	if s.deps != nil {
+		deps, err := s.deps.Outdated(ctx, dir)
+		if err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("dependency check: %v", err))
+		}
+		beads = append(beads, s.outdated(deps, report)...)
+	}
+	if s.linter != nil {
+		result, err := s.linter.Run(ctx, linter.LintRequest{ProjectPath: dir})
+		switch {
+		case err != nil:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("lint: %v", err))
+		case result.Error != "" && len(result.Violations) == 0:
+			report.Warnings = append(report.Warnings, fmt.Sprintf("lint: %s", result.Error))
+		default:
+			beads = append(beads, s.lint(result, report)...)
+		}
+	}
+
+	sort.SliceStable(beads, func(i, j int) bool {
+		if beads[i].Priority != beads[j].Priority {
+			return beads[i].Priority < beads[j].Priority
+		}
+		if ci, cj := complexityRank(beads[i].Complexity), complexityRank(beads[j].Complexity); ci != cj {
+			return ci < cj
+		}
+		return beads[i].Title < beads[j].Title
+	})
+	if len(beads) > maxBeads {
+		report.Omitted = len(beads) - maxBeads
+		beads = beads[:maxBeads]
+	}
+	report.Beads = beads
+	return report, nil
+}
+
+// sourceTree is the source files of a repository, by directory
+type sourceTree struct {
+	files []sourceFile
+	dirs  map[string][]sourceFile // Relative directory -> its files
+}
+
+type sourceFile struct {
+	path string // Relative to the repository, slash-separated
+	abs  string
+	test bool
+}
+
+func walkSources(ctx context.Context, dir string) (*sourceTree, error) {
+	tree := &sourceTree{dirs: map[string][]sourceFile{}}
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if path != dir && (skipDirs[name] || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !sourceExts[filepath.Ext(name)] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > maxFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		f := sourceFile{path: rel, abs: path, test: isTestFile(rel)}
+		tree.files = append(tree.files, f)
+		tree.dirs[pathDir(rel)] = append(tree.dirs[pathDir(rel)], f)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan repository: %w", err)
+	}
+	return tree, nil
+}
+
+// isTestFile recognizes the test file conventions of Go, Python and
+// JavaScript/TypeScript
+func isTestFile(rel string) bool {
+	base := pathBase(rel)
+	switch {
+	case strings.HasSuffix(base, "_test.go"):
+		return true
+	case strings.HasSuffix(base, ".py"):
+		return strings.HasPrefix(base, "test_") || strings.HasSuffix(base, "_test.py")
+	case strings.Contains(base, ".test.") || strings.Contains(base, ".spec."):
+		return true
+	}
+	return strings.Contains("/"+rel, "/__tests__/")
+}
+
+// missingTests proposes tests for each Go package without any, and for
+// Python and JavaScript/TypeScript code when the repository has no tests
+// for it at all
+func (s *Scanner) missingTests(tree *sourceTree, report *Report) []ProposedBead {
+	var beads []ProposedBead
+	dirs := make([]string, 0, len(tree.dirs))
+	for d := range tree.dirs {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		var sources []string
+		lines, tested := 0, false
+		for _, f := range tree.dirs[d] {
+			if !strings.HasSuffix(f.path, ".go") {
+				continue
+			}
+			if f.test {
+				tested = true
+				break
+			}
+			if pathBase(f.path) == "doc.go" {
+				continue
+			}
+			sources = append(sources, f.path)
+			lines += countLines(f.abs)
+		}
+		if tested || len(sources) == 0 {
+			continue
+		}
+		pkg := d
+		if pkg == "" {
+			pkg = "the root package"
+		}
+		title := fmt.Sprintf("Add tests for %s", pkg)
+		desc := fmt.Sprintf("The Go package %s has no tests. Add table-driven tests covering the exported behavior of:\n- %s",
+			pkg, strings.Join(limit(sources), "\n- "))
+		report.Findings[KindMissingTests]++
+		beads = append(beads, s.propose(KindMissingTests, title, desc, models.BeadPriorityP2, sizeComplexity(lines, 200, 1000), sources))
+	}
+
+	for _, lang := range []struct {
+		name string
+		exts []string
+	}{
+		{"Python", []string{".py"}},
+		{"JavaScript/TypeScript", []string{".js", ".jsx", ".ts", ".tsx"}},
+	} {
+		var sources []string
+		tested := false
+		for _, f := range tree.files {
+			if !hasExt(f.path, lang.exts) {
+				continue
+			}
+			if f.test {
+				tested = true
+				break
+			}
+			sources = append(sources, f.path)
+		}
+		if tested || len(sources) == 0 {
+			continue
+		}
+		title := fmt.Sprintf("Set up %s tests", lang.name)
+		desc := fmt.Sprintf("The repository has %d %s source files and no tests for them. Pick a test framework, wire it into the build, and cover the core modules first:\n- %s",
+			len(sources), lang.name, strings.Join(limit(sources), "\n- "))
+		report.Findings[KindMissingTests]++
+		beads = append(beads, s.propose(KindMissingTests, title, desc, models.BeadPriorityP2, sizeComplexity(len(sources), 5, 30), sources))
+	}
+	return beads
+}
+
+// todos proposes one bead per file with TODO-style comments. FIXME, XXX
+// and BUG markers point at known defects and rank higher.
+func (s *Scanner) todos(tree *sourceTree, report *Report) []ProposedBead {
+	var beads []ProposedBead
+	for _, f := range tree.files {
+		var found []string
+		defect := false
+		scanFile(f.abs, func(n int, line string) {
+			m := todoPattern.FindStringSubmatch(line)
+			if m == nil {
+				return
+			}
+			if m[1] != "TODO" && m[1] != "HACK" {
+				defect = true
+			}
+			found = append(found, fmt.Sprintf("%s:%d %s %s", f.path, n, m[1], strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(m[2]), "*/"))))
+		})
+		if len(found) == 0 {
+			continue
+		}
+		report.Findings[KindTodo] += len(found)
+		priority := models.BeadPriorityP3
+		if defect {
+			priority = models.BeadPriorityP2
+		}
+		title := fmt.Sprintf("Resolve %d TODO/FIXME comment(s) in %s", len(found), f.path)
+		desc := fmt.Sprintf("Address or remove these comments, filing separate beads for any that need larger work:\n- %s",
+			strings.Join(limit(found), "\n- "))
+		beads = append(beads, s.propose(KindTodo, title, desc, priority, sizeComplexity(len(found), 1, 5), []string{f.path}))
+	}
+	return beads
+}
+
+// outdated proposes a bead per major upgrade, since those can break the
+// build, and one bead per ecosystem for the minor and patch updates
+func (s *Scanner) outdated(deps []Dependency, report *Report) []ProposedBead {
+	var beads []ProposedBead
+	minor := map[string][]string{}
+	var ecosystems []string
+	for _, d := range deps {
+		report.Findings[KindOutdatedDependency]++
+		if d.Major() {
+			title := fmt.Sprintf("Upgrade %s from %s to %s", d.Name, d.Current, d.Latest)
+			desc := fmt.Sprintf("The %s dependency %s is at %s; %s is a new major version. Read its changelog, migrate the breaking changes, and make sure the tests pass.",
+				d.Ecosystem, d.Name, d.Current, d.Latest)
+			beads = append(beads, s.propose(KindOutdatedDependency, title, desc, models.BeadPriorityP2, provider.ComplexityMedium.String(), nil))
+			continue
+		}
+		if _, ok := minor[d.Ecosystem]; !ok {
+			ecosystems = append(ecosystems, d.Ecosystem)
+		}
+		minor[d.Ecosystem] = append(minor[d.Ecosystem], fmt.Sprintf("%s %s -> %s", d.Name, d.Current, d.Latest))
+	}
+	for _, eco := range ecosystems {
+		updates := minor[eco]
+		title := fmt.Sprintf("Update %d %s dependencies", len(updates), eco)
+		desc := fmt.Sprintf("These %s dependencies have minor or patch releases available. Update them and run the tests:\n- %s",
+			eco, strings.Join(limit(updates), "\n- "))
+		beads = append(beads, s.propose(KindOutdatedDependency, title, desc, models.BeadPriorityP3, sizeComplexity(len(updates), 10, 40), nil))
+	}
+	return beads
+}
+
+// lint proposes one bead per lint rule. Rules reported as errors rank
+// higher than warnings.
+func (s *Scanner) lint(result *linter.LintResult, report *Report) []ProposedBead {
+	byRule := map[string][]linter.Violation{}
+	var rules []string
+	for _, v := range result.Violations {
+		rule := v.Rule
+		if rule == "" {
+			rule = v.Linter
+		}
+		if rule == "" {
+			rule = result.Framework
+		}
+		if _, ok := byRule[rule]; !ok {
+			rules = append(rules, rule)
+		}
+		byRule[rule] = append(byRule[rule], v)
+	}
+
+	var beads []ProposedBead
+	for _, rule := range rules {
+		violations := byRule[rule]
+		report.Findings[KindLint] += len(violations)
+		priority := models.BeadPriorityP3
+		var locations, files []string
+		seen := map[string]bool{}
+		for _, v := range violations {
+			if v.Severity == "error" {
+				priority = models.BeadPriorityP2
+			}
+			locations = append(locations, fmt.Sprintf("%s:%d %s", v.File, v.Line, v.Message))
+			if !seen[v.File] {
+				seen[v.File] = true
+				files = append(files, v.File)
+			}
+		}
+		title := fmt.Sprintf("Fix %d %s lint violation(s)", len(violations), rule)
+		desc := fmt.Sprintf("%s reports these %s violations:\n- %s", result.Framework, rule, strings.Join(limit(locations), "\n- "))
+		beads = append(beads, s.propose(KindLint, title, desc, priority, sizeComplexity(len(violations), 3, 20), files))
+	}
+	return beads
+}
+
+// propose builds a bead, estimating its complexity as the higher of the
+// size of the work and what its text suggests
+func (s *Scanner) propose(kind, title, desc string, priority models.BeadPriority, size string, files []string) ProposedBead {
+	complexity := size
+	if level := s.estimator.EstimateComplexity(title, desc); complexityRank(level.String()) > complexityRank(size) {
+		complexity = level.String()
+	}
+	return ProposedBead{
+		Title:       title,
+		Description: desc,
+		Priority:    priority,
+		Type:        "task",
+		Kind:        kind,
+		Complexity:  complexity,
+		Files:       files,
+	}
+}
+
+// sizeComplexity rates work of size n: simple up to small, medium up to
+// large, complex beyond
+func sizeComplexity(n, small, large int) string {
+	switch {
+	case n <= small:
+		return provider.ComplexitySimple.String()
+	case n <= large:
+		return provider.ComplexityMedium.String()
+	default:
+		return provider.ComplexityComplex.String()
+	}
+}
+
+func complexityRank(c string) int {
+	for _, level := range []provider.ComplexityLevel{provider.ComplexitySimple, provider.ComplexityMedium, provider.ComplexityComplex, provider.ComplexityExtended} {
+		if level.String() == c {
+			return int(level)
+		}
+	}
+	return 0
+}
+
+// scanFile calls fn with each line of a text file, skipping binary files
+func scanFile(path string, fn func(n int, line string)) {
+	data, err := os.ReadFile(path)
+	if err != nil || bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 {
+		return
+	}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), maxFileSize)
+	for n := 1; sc.Scan(); n++ {
+		fn(n, sc.Text())
+	}
+}
+
+func countLines(path string) int {
+	lines := 0
+	scanFile(path, func(int, string) { lines++ })
+	return lines
+}
+
+// majorVersion reads the major version of a version string such as
+// v1.2.3, 4.0.0 or ^2.1
+func majorVersion(v string) string {
+	v = strings.TrimLeft(v, "^~>=v ")
+	major, _, _ := strings.Cut(v, ".")
+	return major
+}
+
+func limit(items []string) []string {
+	if len(items) <= maxListed {
+		return items
+	}
+	return append(items[:maxListed:maxListed], fmt.Sprintf("... and %d more", len(items)-maxListed))
+}
+
+func hasExt(path string, exts []string) bool {
+	for _, ext := range exts {
+		if strings.HasSuffix(path, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathDir(rel string) string {
+	if i := strings.LastIndex(rel, "/"); i >= 0 {
+		return rel[:i]
+	}
+	return ""
+}
+
+func pathBase(rel string) string {
+	return rel[strings.LastIndex(rel, "/")+1:]
+}
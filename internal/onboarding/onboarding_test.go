@@ -0,0 +1,196 @@
+package onboarding
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/linter"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+type fakeLinter struct {
+	result *linter.LintResult
+	err    error
+}
+
+func (f fakeLinter) Run(ctx context.Context, req linter.LintRequest) (*linter.LintResult, error) {
+	return f.result, f.err
+}
+
+type fakeDeps struct {
+	deps []Dependency
+	err  error
+}
+
+func (f fakeDeps) Outdated(ctx context.Context, dir string) ([]Dependency, error) {
+	return f.deps, f.err
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func findBead(report *Report, title string) *ProposedBead {
+	for i := range report.Beads {
+		if report.Beads[i].Title == title {
+			return &report.Beads[i]
+		}
+	}
+	return nil
+}
+
+func TestScan(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"go.mod":                "module example.com/app\n",
+		"main.go":               "package main\n\n// TODO: read the port from the environment\nfunc main() {}\n",
+		"api/server.go":         "package api\n\n// FIXME handle shutdown\nfunc Serve() {}\n\n/* TODO: add metrics */\n",
+		"store/store.go":        "package store\n\nfunc Get() string { return \"TODO\" }\n",
+		"store/store_test.go":   "package store\n",
+		"web/app.js":            "export const x = 1; // HACK until the API is ready\n",
+		"node_modules/dep/x.js": "// TODO not ours\n",
+		"vendor/lib/lib.go":     "package lib\n",
+		".hidden/tool.go":       "package tool\n",
+		"README.md":             "TODO: write docs\n",
+	})
+
+	lint := fakeLinter{result: &linter.LintResult{Framework: "golangci-lint", Violations: []linter.Violation{
+		{File: "api/server.go", Line: 4, Rule: "errcheck", Severity: "error", Message: "unchecked error"},
+		{File: "main.go", Line: 4, Rule: "errcheck", Severity: "error", Message: "unchecked error"},
+		{File: "main.go", Line: 1, Rule: "godot", Severity: "warning", Message: "comment should end in a period"},
+	}}}
+	deps := fakeDeps{deps: []Dependency{
+		{Ecosystem: "go", Name: "github.com/a/b", Current: "v1.2.0", Latest: "v1.3.0"},
+		{Ecosystem: "go", Name: "github.com/c/d", Current: "v1.9.0", Latest: "v2.0.0"},
+		{Ecosystem: "npm", Name: "left-pad", Current: "1.0.0", Latest: "1.0.1"},
+	}}
+
+	report, err := NewScanner(lint, deps).Scan(context.Background(), dir, 0)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if report.Files != 5 {
+		t.Errorf("scanned %d files, want 5 outside skipped directories", report.Files)
+	}
+
+	for _, title := range []string{
+		"Add tests for the root package",
+		"Add tests for api",
+		"Set up JavaScript/TypeScript tests",
+		"Resolve 1 TODO/FIXME comment(s) in main.go",
+		"Resolve 2 TODO/FIXME comment(s) in api/server.go",
+		"Resolve 1 TODO/FIXME comment(s) in web/app.js",
+		"Upgrade github.com/c/d from v1.9.0 to v2.0.0",
+		"Update 1 go dependencies",
+		"Update 1 npm dependencies",
+		"Fix 2 errcheck lint violation(s)",
+		"Fix 1 godot lint violation(s)",
+	} {
+		if findBead(report, title) == nil {
+			t.Errorf("missing proposed bead %q", title)
+		}
+	}
+	if len(report.Beads) != 11 {
+		for _, b := range report.Beads {
+			t.Logf("proposed: %s", b.Title)
+		}
+		t.Errorf("proposed %d beads, want 11", len(report.Beads))
+	}
+	if findBead(report, "Add tests for store") != nil {
+		t.Error("a tested package must not get a missing tests bead")
+	}
+
+	if b := findBead(report, "Resolve 2 TODO/FIXME comment(s) in api/server.go"); b != nil {
+		if b.Priority != models.BeadPriorityP2 || b.Complexity != "medium" {
+			t.Errorf("FIXME bead = P%d %s, want P2 medium", b.Priority, b.Complexity)
+		}
+		if !strings.Contains(b.Description, "api/server.go:3 FIXME handle shutdown") || !strings.Contains(b.Description, "api/server.go:6 TODO add metrics") {
+			t.Errorf("FIXME bead description lists %q", b.Description)
+		}
+	}
+	if b := findBead(report, "Resolve 1 TODO/FIXME comment(s) in main.go"); b != nil && (b.Priority != models.BeadPriorityP3 || b.Complexity != "simple") {
+		t.Errorf("TODO bead = P%d %s, want P3 simple", b.Priority, b.Complexity)
+	}
+	if b := findBead(report, "Fix 2 errcheck lint violation(s)"); b != nil && (b.Priority != models.BeadPriorityP2 || len(b.Files) != 2) {
+		t.Errorf("lint error bead = %+v, want P2 over both files", b)
+	}
+	if report.Findings[KindTodo] != 4 || report.Findings[KindLint] != 3 || report.Findings[KindOutdatedDependency] != 3 {
+		t.Errorf("findings = %v", report.Findings)
+	}
+
+	// Highest priority first, then quick wins
+	for i := 1; i < len(report.Beads); i++ {
+		prev, cur := report.Beads[i-1], report.Beads[i]
+		if prev.Priority > cur.Priority || (prev.Priority == cur.Priority && complexityRank(prev.Complexity) > complexityRank(cur.Complexity)) {
+			t.Errorf("bead %q ranked after %q", cur.Title, prev.Title)
+		}
+	}
+
+	capped, err := NewScanner(lint, deps).Scan(context.Background(), dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(capped.Beads) != 4 || capped.Omitted != 7 {
+		t.Errorf("capped scan proposed %d and omitted %d, want 4 and 7", len(capped.Beads), capped.Omitted)
+	}
+}
+
+func TestScan_ChecksThatCannotRunAreWarnings(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"app.py": "print('hi')\n", "tests/test_app.py": "def test(): pass\n"})
+
+	report, err := NewScanner(
+		fakeLinter{result: &linter.LintResult{Framework: "pylint", Error: "executable file not found"}},
+		fakeDeps{err: errors.New("pip not found")},
+	).Scan(context.Background(), dir, 0)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if len(report.Beads) != 0 {
+		t.Errorf("expected no beads for a tested repository, got %+v", report.Beads)
+	}
+	if len(report.Warnings) != 2 {
+		t.Errorf("warnings = %v, want the lint and dependency failures", report.Warnings)
+	}
+
+	if _, err := NewScanner(nil, nil).Scan(context.Background(), filepath.Join(dir, "missing"), 0); err == nil {
+		t.Error("expected an error for a missing directory")
+	}
+}
+
+func TestParseDependencies(t *testing.T) {
+	goList := `{"Path": "example.com/app", "Main": true}
+{"Path": "github.com/a/b", "Version": "v1.2.0", "Update": {"Path": "github.com/a/b", "Version": "v1.4.0"}}
+{"Path": "github.com/c/d", "Version": "v0.1.0", "Indirect": true, "Update": {"Version": "v0.2.0"}}
+{"Path": "github.com/e/f", "Version": "v1.0.0"}
+`
+	deps, err := parseGoModules(strings.NewReader(goList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 1 || deps[0].Name != "github.com/a/b" || deps[0].Latest != "v1.4.0" || deps[0].Major() {
+		t.Errorf("go deps = %+v, want only the direct minor update", deps)
+	}
+
+	npm := `{"react": {"current": "17.0.2", "wanted": "17.0.2", "latest": "18.2.0"}, "lodash": {"current": "4.17.20", "latest": "4.17.21"}}`
+	deps, err = parseNpmOutdated([]byte(npm))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps) != 2 || deps[0].Name != "lodash" || deps[0].Major() || deps[1].Name != "react" || !deps[1].Major() {
+		t.Errorf("npm deps = %+v", deps)
+	}
+}
@@ -0,0 +1,246 @@
+// Package org groups projects, users, providers and API keys into
+// organizations. Every project and user belongs to exactly one
+// organization; data never crosses from one organization to another.
+//
+// The default organization is the operator's. Everything created before
+// organizations existed belongs to it, and its users keep their view of the
+// whole system, limited only by their roles. Users of any other
+// organization are confined to it: they see only its projects, providers,
+// API keys, activity and notifications, and cannot reach system-wide
+// settings.
+package org
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultID is the operator's organization, which owns everything not
+// explicitly placed in another
+const DefaultID = "default"
+
+// idPattern is what organization IDs may look like; they appear in URLs
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Organization is a tenant of the system
+type Organization struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// DailyBudgetUSD caps what the organization's projects may spend on
+	// providers per UTC day; dispatch stops once it is reached. 0 is no cap.
+	DailyBudgetUSD float64   `json:"daily_budget_usd,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// Request creates or updates an organization. On update, nil fields are
+// left unchanged.
+type Request struct {
+	ID             string   `json:"id,omitempty"` // Only read on create
+	Name           *string  `json:"name,omitempty"`
+	DailyBudgetUSD *float64 `json:"daily_budget_usd,omitempty"`
+}
+
+// Store persists organizations
+type Store interface {
+	UpsertOrganization(o *Organization) error
+	ListOrganizations() ([]*Organization, error)
+	DeleteOrganization(id string) error
+}
+
+// Normalize returns the organization an ID refers to: records saved before
+// organizations existed carry none and belong to the default organization
+func Normalize(id string) string {
+	if id == "" {
+		return DefaultID
+	}
+	return id
+}
+
+// Confined reports whether a caller of organization id is limited to it.
+// The default organization's users, and callers with no organization when
+// authentication is off, see every organization.
+func Confined(id string) bool {
+	return id != "" && id != DefaultID
+}
+
+// Manager keeps the organizations. The default organization always exists.
+// A nil Manager knows only the default organization.
+type Manager struct {
+	store Store // optional; organizations live only in memory without it
+
+	mu   sync.RWMutex
+	orgs map[string]*Organization
+}
+
+// NewManager loads the organizations in store, which may be nil
+func NewManager(store Store) (*Manager, error) {
+	m := &Manager{store: store, orgs: make(map[string]*Organization)}
+	if store != nil {
+		list, err := store.ListOrganizations()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load organizations: %w", err)
+		}
+		for _, o := range list {
+			m.orgs[o.ID] = o
+		}
+	}
+	if _, ok := m.orgs[DefaultID]; !ok {
+		now := time.Now()
+		def := &Organization{ID: DefaultID, Name: "Default", CreatedAt: now, UpdatedAt: now}
+		if err := m.save(def); err != nil {
+			return nil, err
+		}
+		m.orgs[DefaultID] = def
+	}
+	return m, nil
+}
+
+// Get returns a copy of an organization
+func (m *Manager) Get(id string) (*Organization, error) {
+	id = Normalize(id)
+	if m == nil {
+		if id == DefaultID {
+			return &Organization{ID: DefaultID, Name: "Default"}, nil
+		}
+		return nil, fmt.Errorf("organization not found: %s", id)
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	o, ok := m.orgs[id]
+	if !ok {
+		return nil, fmt.Errorf("organization not found: %s", id)
+	}
+	cp := *o
+	return &cp, nil
+}
+
+// Exists reports whether an organization exists
+func (m *Manager) Exists(id string) bool {
+	_, err := m.Get(id)
+	return err == nil
+}
+
+// List returns the organizations sorted by ID
+func (m *Manager) List() []*Organization {
+	if m == nil {
+		def, _ := m.Get(DefaultID)
+		return []*Organization{def}
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	list := make([]*Organization, 0, len(m.orgs))
+	for _, o := range m.orgs {
+		cp := *o
+		list = append(list, &cp)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Create adds an organization
+func (m *Manager) Create(req Request) (*Organization, error) {
+	if m == nil {
+		return nil, fmt.Errorf("organizations are not available")
+	}
+	id := strings.TrimSpace(req.ID)
+	if !idPattern.MatchString(id) {
+		return nil, fmt.Errorf("id must be lowercase letters, digits and dashes, starting with a letter or digit")
+	}
+	now := time.Now()
+	o := &Organization{ID: id, Name: id, CreatedAt: now, UpdatedAt: now}
+	if err := req.apply(o); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, exists := m.orgs[id]; exists {
+		return nil, fmt.Errorf("organization %s already exists", id)
+	}
+	if err := m.save(o); err != nil {
+		return nil, err
+	}
+	m.orgs[id] = o
+	cp := *o
+	return &cp, nil
+}
+
+// Update changes an organization's name or budget
+func (m *Manager) Update(id string, req Request) (*Organization, error) {
+	if m == nil {
+		return nil, fmt.Errorf("organizations are not available")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.orgs[Normalize(id)]
+	if !ok {
+		return nil, fmt.Errorf("organization not found: %s", id)
+	}
+	o := *existing
+	if err := req.apply(&o); err != nil {
+		return nil, err
+	}
+	o.UpdatedAt = time.Now()
+	if err := m.save(&o); err != nil {
+		return nil, err
+	}
+	*existing = o
+	return &o, nil
+}
+
+// Delete removes an organization. The default organization cannot be
+// deleted; callers check that nothing still belongs to the organization.
+func (m *Manager) Delete(id string) error {
+	if m == nil {
+		return fmt.Errorf("organizations are not available")
+	}
+	if Normalize(id) == DefaultID {
+		return fmt.Errorf("the default organization cannot be deleted")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.orgs[id]; !ok {
+		return fmt.Errorf("organization not found: %s", id)
+	}
+	if m.store != nil {
+		if err := m.store.DeleteOrganization(id); err != nil {
+			return fmt.Errorf("failed to delete organization: %w", err)
+		}
+	}
+	delete(m.orgs, id)
+	return nil
+}
+
+// save writes an organization to the store, if there is one
+func (m *Manager) save(o *Organization) error {
+	if m.store == nil {
+		return nil
+	}
+	if err := m.store.UpsertOrganization(o); err != nil {
+		return fmt.Errorf("failed to save organization: %w", err)
+	}
+	return nil
+}
+
+// apply copies the set fields of req onto o
+func (req Request) apply(o *Organization) error {
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return fmt.Errorf("name must not be empty")
+		}
+		o.Name = name
+	}
+	if req.DailyBudgetUSD != nil {
+		if *req.DailyBudgetUSD < 0 {
+			return fmt.Errorf("daily_budget_usd must not be negative")
+		}
+		o.DailyBudgetUSD = *req.DailyBudgetUSD
+	}
+	return nil
+}
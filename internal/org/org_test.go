@@ -0,0 +1,122 @@
+package org
+
+import (
+	"fmt"
+	"testing"
+)
+
+type memStore struct {
+	orgs map[string]Organization
+}
+
+func (s *memStore) UpsertOrganization(o *Organization) error {
+	s.orgs[o.ID] = *o
+	return nil
+}
+
+func (s *memStore) ListOrganizations() ([]*Organization, error) {
+	var list []*Organization
+	for _, o := range s.orgs {
+		cp := o
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+func (s *memStore) DeleteOrganization(id string) error {
+	if _, ok := s.orgs[id]; !ok {
+		return fmt.Errorf("no organization %s", id)
+	}
+	delete(s.orgs, id)
+	return nil
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestManager_DefaultOrganizationAlwaysExists(t *testing.T) {
+	store := &memStore{orgs: map[string]Organization{}}
+	m, err := NewManager(store)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if !m.Exists(DefaultID) || !m.Exists("") {
+		t.Fatal("expected the default organization to exist")
+	}
+	if _, ok := store.orgs[DefaultID]; !ok {
+		t.Error("expected the default organization to be saved")
+	}
+	if err := m.Delete(DefaultID); err == nil {
+		t.Error("expected deleting the default organization to fail")
+	}
+
+	var nilMgr *Manager
+	if !nilMgr.Exists(DefaultID) || nilMgr.Exists("acme") || len(nilMgr.List()) != 1 {
+		t.Error("a nil manager should know only the default organization")
+	}
+}
+
+func TestManager_CreateUpdateDelete(t *testing.T) {
+	store := &memStore{orgs: map[string]Organization{}}
+	m, err := NewManager(store)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	budget := 25.0
+	o, err := m.Create(Request{ID: "acme", Name: strPtr("Acme Corp"), DailyBudgetUSD: &budget})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if o.Name != "Acme Corp" || o.DailyBudgetUSD != 25 {
+		t.Errorf("created %+v", o)
+	}
+	if _, err := m.Create(Request{ID: "acme"}); err == nil {
+		t.Error("expected a duplicate ID to be rejected")
+	}
+	for _, id := range []string{"", "Acme", "-acme", "acme/x"} {
+		if _, err := m.Create(Request{ID: id}); err == nil {
+			t.Errorf("expected ID %q to be rejected", id)
+		}
+	}
+
+	negative := -1.0
+	if _, err := m.Update("acme", Request{DailyBudgetUSD: &negative}); err == nil {
+		t.Error("expected a negative budget to be rejected")
+	}
+	if _, err := m.Update("acme", Request{Name: strPtr(" ")}); err == nil {
+		t.Error("expected an empty name to be rejected")
+	}
+	o, err = m.Update("acme", Request{Name: strPtr("Acme")})
+	if err != nil || o.Name != "Acme" || o.DailyBudgetUSD != 25 {
+		t.Fatalf("Update = %+v, %v", o, err)
+	}
+
+	// A new manager over the same store sees the saved organizations
+	reloaded, err := NewManager(store)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	list := reloaded.List()
+	if len(list) != 2 || list[0].ID != "acme" || list[1].ID != DefaultID {
+		t.Errorf("List = %+v, want acme and default", list)
+	}
+
+	if err := m.Delete("acme"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if m.Exists("acme") {
+		t.Error("expected acme to be gone")
+	}
+	if _, ok := store.orgs["acme"]; ok {
+		t.Error("expected acme to be deleted from the store")
+	}
+}
+
+func TestNormalizeAndConfined(t *testing.T) {
+	if Normalize("") != DefaultID || Normalize("acme") != "acme" {
+		t.Error("Normalize should map only the empty ID to the default organization")
+	}
+	if Confined("") || Confined(DefaultID) || !Confined("acme") {
+		t.Error("only organizations other than the default should be confined")
+	}
+}
@@ -0,0 +1,373 @@
+package patch
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/codechunk"
+)
+
+// Strategies that placed a hunk, from strictest to loosest
+const (
+	StrategyExact      = "exact"      // At the expected line
+	StrategyOffset     = "offset"     // Elsewhere in the file, unchanged
+	StrategyWhitespace = "whitespace" // Ignoring indentation and trailing space
+	StrategyFuzz       = "fuzz"       // With outer context lines dropped
+	StrategySymbol     = "symbol"     // Replacing the file's definition of the same name
+)
+
+// maxFuzz is how many context lines may be dropped from each end of a
+// hunk, as with patch(1)
+const maxFuzz = 2
+
+// Placement is where a hunk was applied
+type Placement struct {
+	Hunk     int    `json:"hunk"` // 1-based
+	Line     int    `json:"line"` // 1-based line of the original file
+	Strategy string `json:"strategy"`
+}
+
+// Conflict describes a hunk that could not be placed
+type Conflict struct {
+	Path     string   `json:"path"`
+	Hunk     int      `json:"hunk"`           // 1-based
+	Line     int      `json:"line,omitempty"` // Where the hunk expected to start
+	Reason   string   `json:"reason"`
+	Expected []string `json:"expected,omitempty"` // Lines the hunk looked for
+	// The region of the file most like the expected lines
+	NearestLine int      `json:"nearest_line,omitempty"`
+	Nearest     []string `json:"nearest,omitempty"`
+	Similarity  float64  `json:"similarity,omitempty"` // Share of matching lines, 0-1
+}
+
+// maxConflictLines caps the lines quoted in a conflict message
+const maxConflictLines = 12
+
+func (c Conflict) String() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s hunk %d", c.Path, c.Hunk)
+	if c.Line > 0 {
+		fmt.Fprintf(&sb, " (expected at line %d)", c.Line)
+	}
+	fmt.Fprintf(&sb, ": %s", c.Reason)
+	if c.NearestLine == 0 {
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "; closest match is line %d (%.0f%% of lines equal):", c.NearestLine, c.Similarity*100)
+	shown := 0
+	for i, want := range c.Expected {
+		if i >= len(c.Nearest) || shown >= maxConflictLines {
+			break
+		}
+		if strings.TrimSpace(want) == strings.TrimSpace(c.Nearest[i]) {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  line %d: patch expects %q, file has %q", c.NearestLine+i, want, c.Nearest[i])
+		shown++
+	}
+	return sb.String()
+}
+
+// ConflictError is returned for a patch with hunks that could not be placed
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	parts := make([]string, len(e.Conflicts))
+	for i, c := range e.Conflicts {
+		parts[i] = c.String()
+	}
+	return fmt.Sprintf("%d hunk(s) did not apply:\n%s", len(e.Conflicts), strings.Join(parts, "\n"))
+}
+
+// Apply applies the hunks of fp to content. Every hunk is tried, so a
+// ConflictError lists all the hunks that failed, not just the first.
+func Apply(content string, fp FilePatch) (string, []Placement, error) {
+	lines := strings.Split(content, "\n")
+	if content == "" {
+		lines = nil
+	}
+	var out []string
+	var placements []Placement
+	var conflicts []Conflict
+	next := 0 // First line of the file not yet copied to out
+	drift := 0
+
+	for n, hunk := range fp.Hunks {
+		old := hunk.Old()
+		if len(old) == 0 {
+			// Pure insertion: at the expected line, or the end of the file
+			at := hunk.OldStart + drift
+			if hunk.OldStart == 0 || at > len(lines) {
+				at = len(lines)
+				if at > 0 && lines[at-1] == "" {
+					at-- // Before the newline ending the file
+				}
+			}
+			if at < next {
+				at = next
+			}
+			out = append(out, lines[next:at]...)
+			out = append(out, insertions(hunk.Lines)...)
+			next = at
+			placements = append(placements, Placement{Hunk: n + 1, Line: at + 1, Strategy: StrategyExact})
+			continue
+		}
+
+		expected := hunk.OldStart - 1 + drift
+		if hunk.OldStart == 0 {
+			expected = next
+		}
+		at, body, strategy := locate(lines, next, expected, hunk)
+		if at < 0 {
+			if start, end, ok := symbol(fp.Path, lines, next, hunk); ok {
+				out = append(out, lines[next:start]...)
+				out = append(out, hunk.New()...)
+				next = end
+				placements = append(placements, Placement{Hunk: n + 1, Line: start + 1, Strategy: StrategySymbol})
+				continue
+			}
+			conflicts = append(conflicts, conflict(fp.Path, n+1, hunk, lines, expected))
+			continue
+		}
+		out = append(out, lines[next:at]...)
+		matched := lines[at : at+len(oldLines(body))]
+		out = append(out, rewrite(body, matched)...)
+		next = at + len(matched)
+		if hunk.OldStart > 0 {
+			drift = at - (hunk.OldStart - 1)
+		}
+		placements = append(placements, Placement{Hunk: n + 1, Line: at + 1, Strategy: strategy})
+	}
+	if len(conflicts) > 0 {
+		return content, nil, &ConflictError{Conflicts: conflicts}
+	}
+	out = append(out, lines[next:]...)
+	result := strings.Join(out, "\n")
+	if content == "" && result != "" {
+		// Every added line of a new file ends with a newline
+		result += "\n"
+	}
+	return result, placements, nil
+}
+
+// locate finds where the hunk's old lines are, at or after from. It
+// returns the line, the hunk lines that matched (fewer under fuzz) and the
+// strategy, or -1.
+func locate(lines []string, from, expected int, hunk Hunk) (int, []Line, string) {
+	body := hunk.Lines
+	if at := find(lines, from, expected, oldLines(body), exactEqual); at >= 0 {
+		if at == expected {
+			return at, body, StrategyExact
+		}
+		return at, body, StrategyOffset
+	}
+	if at := find(lines, from, expected, oldLines(body), trimmedEqual); at >= 0 {
+		return at, body, StrategyWhitespace
+	}
+	for fuzz := 1; fuzz <= maxFuzz; fuzz++ {
+		trimmed, dropped := dropContext(body, fuzz)
+		if trimmed == nil {
+			break
+		}
+		if at := find(lines, from, expected+dropped, oldLines(trimmed), trimmedEqual); at >= 0 {
+			return at, trimmed, StrategyFuzz
+		}
+	}
+	return -1, nil, ""
+}
+
+// symbol places a hunk whose old lines are one whole function, method or
+// type, typically copied from a stale read, over the file's current
+// definition of that name. It returns the lines the definition spans, at
+// or after from, when the file has exactly one such definition of similar
+// size.
+func symbol(path string, lines []string, from int, hunk Hunk) (int, int, bool) {
+	if !codechunk.Supported(path) {
+		return 0, 0, false
+	}
+	oldText := dedent(strings.Join(hunk.Old(), "\n"))
+	var old []codechunk.Chunk
+	for _, c := range codechunk.Split(path, oldText) {
+		if c.Kind != codechunk.KindBlock {
+			old = append(old, c)
+		}
+	}
+	if len(old) != 1 || strings.TrimSpace(old[0].Text) != strings.TrimSpace(oldText) {
+		return 0, 0, false
+	}
+	current := codechunk.Find(codechunk.Split(path, strings.Join(lines, "\n")), old[0].Name)
+	if len(current) != 1 || current[0].StartLine-1 < from {
+		return 0, 0, false
+	}
+	target := current[0]
+	if target.Lines() > old[0].Lines()*2 || old[0].Lines() > target.Lines()*2 {
+		return 0, 0, false
+	}
+	start := target.StartLine - 1
+	if old[0].DeclLine == old[0].StartLine {
+		// The hunk has no doc comment, so keep the current one
+		start = target.DeclLine - 1
+	}
+	return start, target.EndLine, true
+}
+
+// dedent removes the indentation common to the non-blank lines of text
+func dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	prefix := ""
+	first := true
+	for _, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		indent := l[:len(l)-len(strings.TrimLeft(l, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimPrefix(l, prefix)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// find returns the match of old nearest to expected at or after from
+func find(lines []string, from, expected int, old []string, equal func(a, b string) bool) int {
+	if len(old) == 0 || len(old) > len(lines)-from {
+		return -1
+	}
+	if expected < from {
+		expected = from
+	}
+	last := len(lines) - len(old)
+	if expected > last {
+		expected = last
+	}
+	for d := 0; expected-d >= from || expected+d <= last; d++ {
+		if at := expected - d; at >= from && matchesAt(lines, at, old, equal) {
+			return at
+		}
+		if at := expected + d; d > 0 && at <= last && matchesAt(lines, at, old, equal) {
+			return at
+		}
+	}
+	return -1
+}
+
+func matchesAt(lines []string, at int, old []string, equal func(a, b string) bool) bool {
+	for i, want := range old {
+		if !equal(lines[at+i], want) {
+			return false
+		}
+	}
+	return true
+}
+
+func exactEqual(a, b string) bool { return a == b }
+
+func trimmedEqual(a, b string) bool { return strings.TrimSpace(a) == strings.TrimSpace(b) }
+
+// dropContext removes up to n context lines from each end of a hunk,
+// returning how many were dropped from the start, or nil when the hunk
+// has no context left to drop
+func dropContext(body []Line, n int) ([]Line, int) {
+	start, end := 0, len(body)
+	for start < n && start < end && body[start].Op == OpContext {
+		start++
+	}
+	for len(body)-end < n && end > start && body[end-1].Op == OpContext {
+		end--
+	}
+	if start == 0 && end == len(body) {
+		return nil, 0
+	}
+	if len(oldLines(body[start:end])) == 0 {
+		return nil, 0
+	}
+	return body[start:end], start
+}
+
+func oldLines(body []Line) []string {
+	return Hunk{Lines: body}.Old()
+}
+
+func insertions(body []Line) []string {
+	var added []string
+	for _, l := range body {
+		if l.Op == OpInsert {
+			added = append(added, l.Text)
+		}
+	}
+	return added
+}
+
+// rewrite produces the new text of a matched region, keeping the file's own
+// text for context lines so whitespace-tolerant matches do not reformat them
+func rewrite(body []Line, matched []string) []string {
+	var out []string
+	i := 0
+	for _, l := range body {
+		switch l.Op {
+		case OpContext:
+			out = append(out, matched[i])
+			i++
+		case OpDelete:
+			i++
+		case OpInsert:
+			out = append(out, l.Text)
+		}
+	}
+	return out
+}
+
+// conflict explains why a hunk could not be placed, quoting the region of
+// the file most like what it expected
+func conflict(path string, n int, hunk Hunk, lines []string, expected int) Conflict {
+	old := hunk.Old()
+	c := Conflict{Path: path, Hunk: n, Expected: old}
+	if hunk.OldStart > 0 {
+		c.Line = hunk.OldStart
+	}
+	switch {
+	case len(lines) == 0:
+		c.Reason = "the file is empty"
+		return c
+	case len(old) > len(lines):
+		c.Reason = fmt.Sprintf("the hunk expects %d lines but the file has %d", len(old), len(lines))
+		return c
+	}
+	best, bestScore := -1, 0
+	for at := 0; at+len(old) <= len(lines); at++ {
+		score := 0
+		for i, want := range old {
+			if trimmedEqual(lines[at+i], want) {
+				score++
+			}
+		}
+		if score > bestScore || (score == bestScore && best >= 0 && abs(at-expected) < abs(best-expected)) {
+			best, bestScore = at, score
+		}
+	}
+	c.Reason = fmt.Sprintf("the %d lines it removes or keeps as context were not found", len(old))
+	if bestScore == 0 {
+		c.Reason += " anywhere in the file"
+		return c
+	}
+	c.NearestLine = best + 1
+	c.Nearest = append([]string(nil), lines[best:best+len(old)]...)
+	c.Similarity = float64(bestScore) / float64(len(old))
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
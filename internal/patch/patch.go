@@ -0,0 +1,262 @@
+// Package patch parses unified diffs and search/replace blocks and applies
+// them to file contents. Hunks whose line numbers or whitespace drifted
+// from the file are re-anchored, and hunks that cannot be placed are
+// reported with the closest region of the file, so an agent can fix its
+// patch instead of guessing why it failed.
+package patch
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Line operations of a hunk
+const (
+	OpContext = ' '
+	OpDelete  = '-'
+	OpInsert  = '+'
+)
+
+// Line is one line of a hunk
+type Line struct {
+	Op   byte
+	Text string
+}
+
+// Hunk is a change to one region of a file
+type Hunk struct {
+	// OldStart is the 1-based line the hunk expects to start at, or 0 when
+	// unknown (search/replace blocks)
+	OldStart int
+	Lines    []Line
+}
+
+// Old returns the lines the hunk expects to find
+func (h Hunk) Old() []string {
+	var old []string
+	for _, l := range h.Lines {
+		if l.Op != OpInsert {
+			old = append(old, l.Text)
+		}
+	}
+	return old
+}
+
+// New returns the lines the hunk leaves in their place
+func (h Hunk) New() []string {
+	var lines []string
+	for _, l := range h.Lines {
+		if l.Op != OpDelete {
+			lines = append(lines, l.Text)
+		}
+	}
+	return lines
+}
+
+// FilePatch is the changes to one file
+type FilePatch struct {
+	Path   string // Project-relative path of the file after the patch
+	Create bool   // The file is new
+	Delete bool   // The file is removed
+	Hunks  []Hunk
+}
+
+// Formats of a patch
+const (
+	FormatUnified       = "unified"
+	FormatSearchReplace = "search_replace"
+)
+
+const (
+	searchMarker  = "<<<<<<< SEARCH"
+	dividerMarker = "======="
+	replaceMarker = ">>>>>>> REPLACE"
+)
+
+// DetectFormat tells unified diffs from search/replace blocks
+func DetectFormat(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) == searchMarker {
+			return FormatSearchReplace
+		}
+	}
+	return FormatUnified
+}
+
+// Parse parses a unified diff or a series of search/replace blocks
+func Parse(text string) ([]FilePatch, error) {
+	if DetectFormat(text) == FormatSearchReplace {
+		return parseSearchReplace(text)
+	}
+	return parseUnified(text)
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parseUnified parses a unified diff. Line counts in hunk headers are
+// ignored, as hand-written diffs often get them wrong; a hunk ends at the
+// next header. Blank lines inside a hunk are taken as blank context lines.
+func parseUnified(text string) ([]FilePatch, error) {
+	var files []FilePatch
+	var file *FilePatch
+	var hunk *Hunk
+	flush := func() {
+		if file == nil {
+			return
+		}
+		if hunk != nil {
+			file.Hunks = append(file.Hunks, *hunk)
+			hunk = nil
+		}
+		if file.Path != "" && (len(file.Hunks) > 0 || file.Create || file.Delete) {
+			files = append(files, *file)
+		}
+		file = nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			flush()
+			file = &FilePatch{}
+			if parts := strings.Fields(line); len(parts) >= 4 {
+				file.Path = strings.TrimPrefix(parts[3], "b/")
+			}
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if file == nil || hunk != nil || len(file.Hunks) > 0 {
+				flush()
+				file = &FilePatch{}
+			}
+			oldPath, newPath := diffPath(line[4:], "a/"), diffPath(lines[i+1][4:], "b/")
+			switch {
+			case oldPath == "/dev/null":
+				file.Create, file.Path = true, newPath
+			case newPath == "/dev/null":
+				file.Delete, file.Path = true, oldPath
+			default:
+				file.Path = newPath
+			}
+			i++
+		case strings.HasPrefix(line, "new file mode") && file != nil:
+			file.Create = true
+		case strings.HasPrefix(line, "deleted file mode") && file != nil:
+			file.Delete = true
+		case strings.HasPrefix(line, "@@"):
+			if file == nil {
+				return nil, fmt.Errorf("line %d: hunk without a file header", i+1)
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			if hunk != nil {
+				file.Hunks = append(file.Hunks, *hunk)
+			}
+			start, _ := strconv.Atoi(m[1])
+			hunk = &Hunk{OldStart: start}
+		case hunk != nil:
+			switch {
+			case line == "":
+				hunk.Lines = append(hunk.Lines, Line{Op: OpContext})
+			case line[0] == OpContext || line[0] == OpDelete || line[0] == OpInsert:
+				hunk.Lines = append(hunk.Lines, Line{Op: line[0], Text: line[1:]})
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+			default:
+				file.Hunks = append(file.Hunks, *hunk)
+				hunk = nil
+			}
+		}
+	}
+	flush()
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file changes found in patch")
+	}
+	return files, nil
+}
+
+func diffPath(field, prefix string) string {
+	path := field
+	if i := strings.IndexByte(path, '\t'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return path
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// parseSearchReplace parses blocks of the form
+//
+//	path/to/file
+//	<<<<<<< SEARCH
+//	old lines
+//	=======
+//	new lines
+//	>>>>>>> REPLACE
+//
+// A block without a path line applies to the file of the block before it;
+// an empty SEARCH creates the file.
+func parseSearchReplace(text string) ([]FilePatch, error) {
+	var files []FilePatch
+	index := make(map[string]int)
+	path := ""
+	lines := strings.Split(text, "\n")
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		if trimmed != searchMarker {
+			if trimmed != "" && !strings.HasPrefix(trimmed, "```") {
+				path = trimmed
+			}
+			continue
+		}
+		if path == "" {
+			return nil, fmt.Errorf("line %d: search block without a file path", i+1)
+		}
+		var search, replace []string
+		section := &search
+		closed := false
+		for i++; i < len(lines); i++ {
+			switch strings.TrimSpace(lines[i]) {
+			case dividerMarker:
+				if section == &search {
+					section = &replace
+					continue
+				}
+			case replaceMarker:
+				closed = true
+			}
+			if closed {
+				break
+			}
+			*section = append(*section, lines[i])
+		}
+		if !closed || section != &replace {
+			return nil, fmt.Errorf("search block for %s is not closed with %q", path, replaceMarker)
+		}
+
+		hunk := Hunk{}
+		for _, l := range search {
+			hunk.Lines = append(hunk.Lines, Line{Op: OpDelete, Text: l})
+		}
+		for _, l := range replace {
+			hunk.Lines = append(hunk.Lines, Line{Op: OpInsert, Text: l})
+		}
+		n, ok := index[path]
+		if !ok {
+			n = len(files)
+			index[path] = n
+			files = append(files, FilePatch{Path: path, Create: len(search) == 0})
+		}
+		files[n].Hunks = append(files[n].Hunks, hunk)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no search/replace blocks found")
+	}
+	return files, nil
+}
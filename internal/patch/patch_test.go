@@ -0,0 +1,201 @@
+package patch
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const source = `package calc
+
+import "fmt"
+
+func Add(a, b int) int {
+	return a + b
+}
+
+func Sub(a, b int) int {
+	return a - b
+}
+
+func Print(n int) {
+	fmt.Println(n)
+}
+`
+
+func applyOne(t *testing.T, content, text string) (string, []Placement, error) {
+	t.Helper()
+	files, err := Parse(text)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("parsed %d files, want 1", len(files))
+	}
+	return Apply(content, files[0])
+}
+
+func TestParseUnified(t *testing.T) {
+	files, err := Parse(`diff --git a/calc.go b/calc.go
+index 1234..5678 100644
+--- a/calc.go
++++ b/calc.go
+@@ -5,3 +5,3 @@ import "fmt"
+ func Add(a, b int) int {
+-	return a + b
++	return b + a
+
+@@ -13,1 +13,1 @@
+-func Print(n int) {
++func Print(n int64) {
+diff --git a/new.go b/new.go
+new file mode 100644
+--- /dev/null
++++ b/new.go
+@@ -0,0 +1,1 @@
++package calc
+--- a/old.go
++++ /dev/null
+@@ -1 +0,0 @@
+-package calc
+`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("parsed %d files, want 3", len(files))
+	}
+	calc := files[0]
+	if calc.Path != "calc.go" || len(calc.Hunks) != 2 || calc.Hunks[0].OldStart != 5 || calc.Hunks[1].OldStart != 13 {
+		t.Errorf("calc.go = %+v", calc)
+	}
+	// The blank line closing the first hunk is blank context
+	if got := calc.Hunks[0].Old(); len(got) != 3 || got[2] != "" {
+		t.Errorf("old lines of hunk 1 = %q", got)
+	}
+	if !files[1].Create || files[1].Path != "new.go" || !files[2].Delete || files[2].Path != "old.go" {
+		t.Errorf("new and deleted files = %+v, %+v", files[1], files[2])
+	}
+
+	if _, err := Parse("not a patch"); err == nil {
+		t.Error("expected an error for text without changes")
+	}
+	if _, err := Parse("--- a/x\n+++ b/x\n@@ nonsense @@\n"); err == nil {
+		t.Error("expected an error for a malformed hunk header")
+	}
+}
+
+func TestParseSearchReplace(t *testing.T) {
+	files, err := Parse("calc.go\n```go\n<<<<<<< SEARCH\n\treturn a + b\n=======\n\treturn b + a\n>>>>>>> REPLACE\n```\n\n<<<<<<< SEARCH\n\treturn a - b\n=======\n\treturn b - a\n>>>>>>> REPLACE\n\nnotes.md\n<<<<<<< SEARCH\n=======\n# Notes\n>>>>>>> REPLACE\n")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if len(files) != 2 || files[0].Path != "calc.go" || len(files[0].Hunks) != 2 || files[1].Path != "notes.md" || !files[1].Create {
+		t.Fatalf("files = %+v", files)
+	}
+	if _, err := Parse("calc.go\n<<<<<<< SEARCH\nx\n=======\ny\n"); err == nil || !strings.Contains(err.Error(), "not closed") {
+		t.Errorf("unclosed block = %v", err)
+	}
+}
+
+func TestApplyStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		patch    string
+		strategy string
+		want     string
+	}{
+		{
+			name:     "exact",
+			patch:    "--- a/calc.go\n+++ b/calc.go\n@@ -5,3 +5,3 @@\n func Add(a, b int) int {\n-\treturn a + b\n+\treturn b + a\n }\n",
+			strategy: StrategyExact,
+			want:     strings.Replace(source, "a + b", "b + a", 1),
+		},
+		{
+			name:     "wrong line numbers",
+			patch:    "--- a/calc.go\n+++ b/calc.go\n@@ -1,3 +1,3 @@\n func Sub(a, b int) int {\n-\treturn a - b\n+\treturn b - a\n }\n",
+			strategy: StrategyOffset,
+			want:     strings.Replace(source, "a - b", "b - a", 1),
+		},
+		{
+			name:     "indentation changed",
+			patch:    "--- a/calc.go\n+++ b/calc.go\n@@ -13,3 +13,3 @@\n func Print(n int) {\n-    fmt.Println(n)\n+\tfmt.Println(n + 1)\n }\n",
+			strategy: StrategyWhitespace,
+			want:     strings.Replace(source, "fmt.Println(n)", "fmt.Println(n + 1)", 1),
+		},
+		{
+			name:     "stale outer context",
+			patch:    "--- a/calc.go\n+++ b/calc.go\n@@ -9,4 +9,4 @@\n // Sub subtracts\n func Sub(a, b int) int {\n-\treturn a - b\n+\treturn b - a\n }\n",
+			strategy: StrategyFuzz,
+			want:     strings.Replace(source, "a - b", "b - a", 1),
+		},
+		{
+			name:     "search and replace",
+			patch:    "calc.go\n<<<<<<< SEARCH\n  return a + b\n=======\n\treturn a + b + 0\n>>>>>>> REPLACE\n",
+			strategy: StrategyWhitespace,
+			want:     strings.Replace(source, "a + b", "a + b + 0", 1),
+		},
+		{
+			name:     "stale definition",
+			patch:    "calc.go\n<<<<<<< SEARCH\nfunc Sub(a, b int) int {\n\treturn a - b - 0\n}\n=======\nfunc Sub(a, b int) int {\n\treturn b - a\n}\n>>>>>>> REPLACE\n",
+			strategy: StrategySymbol,
+			want:     strings.Replace(source, "a - b", "b - a", 1),
+		},
+		{
+			name:     "insertion",
+			patch:    "--- a/calc.go\n+++ b/calc.go\n@@ -15,0 +16,2 @@\n+\n+func Zero() int { return 0 }\n",
+			strategy: StrategyExact,
+			want:     source + "\nfunc Zero() int { return 0 }\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, placements, err := applyOne(t, source, tt.patch)
+			if err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if len(placements) != 1 || placements[0].Strategy != tt.strategy {
+				t.Errorf("placements = %+v, want strategy %s", placements, tt.strategy)
+			}
+			if got != tt.want {
+				t.Errorf("result:\n%s", got)
+			}
+		})
+	}
+}
+
+func TestApplyCreatesFile(t *testing.T) {
+	got, _, err := applyOne(t, "", "--- /dev/null\n+++ b/new.go\n@@ -0,0 +1,2 @@\n+package calc\n+\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "package calc\n\n" {
+		t.Errorf("new file = %q", got)
+	}
+}
+
+func TestApplyConflicts(t *testing.T) {
+	patch := "--- a/calc.go\n+++ b/calc.go\n@@ -5,2 +5,2 @@\n func Add(a, b int) int {\n-\treturn a * b\n+\treturn b * a\n@@ -20,2 +20,2 @@\n-nothing like this\n+at all\n"
+	got, _, err := applyOne(t, source, patch)
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		t.Fatalf("expected a ConflictError, got %v", err)
+	}
+	if got != source {
+		t.Error("a failed patch must leave the content unchanged")
+	}
+	if len(conflictErr.Conflicts) != 2 {
+		t.Fatalf("conflicts = %+v", conflictErr.Conflicts)
+	}
+	first := conflictErr.Conflicts[0]
+	if first.Hunk != 1 || first.Line != 5 || first.NearestLine != 5 || first.Similarity != 0.5 {
+		t.Errorf("first conflict = %+v", first)
+	}
+	message := err.Error()
+	if !strings.Contains(message, `line 6: patch expects "\treturn a * b", file has "\treturn a + b"`) {
+		t.Errorf("message does not show the differing line:\n%s", message)
+	}
+	if !strings.Contains(message, "hunk 2 (expected at line 20): the 1 lines it removes or keeps as context were not found anywhere in the file") {
+		t.Errorf("message does not explain the second hunk:\n%s", message)
+	}
+}
@@ -33,6 +33,12 @@ func NewManager(storage analytics.Storage, config *AnalysisConfig) *Manager {
 	}
 }
 
+// SetPricer makes prompt analysis price saved tokens from the pricing
+// catalog
+func (m *Manager) SetPricer(p analytics.Pricer) {
+	m.promptOptimizer.SetPricer(p)
+}
+
 // AnalyzeAll performs comprehensive analysis across all dimensions
 func (m *Manager) AnalyzeAll(ctx context.Context) (*ComprehensiveReport, error) {
 	// Run pattern analysis
@@ -18,6 +18,7 @@ import (
 type PromptOptimizer struct {
 	storage analytics.Storage
 	config  *PromptAnalysisConfig
+	pricer  analytics.Pricer
 }
 
 // PromptAnalysisConfig configures prompt analysis behavior
@@ -82,6 +83,26 @@ func NewPromptOptimizer(storage analytics.Storage, config *PromptAnalysisConfig)
 	}
 }
 
+// SetPricer prices saved prompt tokens at the pricing catalog's input
+// price for the model rather than the request's average cost per token
+func (p *PromptOptimizer) SetPricer(pricer analytics.Pricer) {
+	p.pricer = pricer
+}
+
+// promptTokenCost returns what tokens prompt tokens of a logged request's
+// model cost
+func (p *PromptOptimizer) promptTokenCost(log *analytics.RequestLog, tokens int64) float64 {
+	if p.pricer != nil {
+		if cost, ok := p.pricer.Cost(log.ProviderID, log.ModelName, tokens, 0, 0); ok {
+			return cost
+		}
+	}
+	if log.TotalTokens == 0 {
+		return 0
+	}
+	return float64(tokens) * log.CostUSD / float64(log.TotalTokens)
+}
+
 // AnalyzePrompts analyzes recent prompts and generates optimization suggestions
 func (p *PromptOptimizer) AnalyzePrompts(ctx context.Context) (*PromptAnalysisReport, error) {
 	// Fetch logs within time window
@@ -210,8 +231,7 @@ func (p *PromptOptimizer) detectVerbosity(log *analytics.RequestLog, prompt stri
 	}
 
 	// Estimate cost savings
-	avgCostPerToken := log.CostUSD / float64(log.TotalTokens)
-	costSavings := float64(tokenSavings) * avgCostPerToken
+	costSavings := p.promptTokenCost(log, tokenSavings)
 	monthlySavings := costSavings * 30 * 7 / p.config.TimeWindow.Hours() * 24
 
 	// Generate optimized version (truncated for display)
@@ -274,8 +294,7 @@ func (p *PromptOptimizer) detectRepetition(log *analytics.RequestLog, prompt str
 		return nil
 	}
 
-	avgCostPerToken := log.CostUSD / float64(log.TotalTokens)
-	costSavings := float64(tokenSavings) * avgCostPerToken
+	costSavings := p.promptTokenCost(log, tokenSavings)
 	monthlySavings := costSavings * 30 * 7 / p.config.TimeWindow.Hours() * 24
 
 	optimizedPrompt := p.generateOptimizedPrompt(prompt, fmt.Sprintf("Remove repeated phrase: '%s'", mostRepeated))
@@ -328,8 +347,7 @@ func (p *PromptOptimizer) detectUnclearInstructions(log *analytics.RequestLog, p
 		return nil
 	}
 
-	avgCostPerToken := log.CostUSD / float64(log.TotalTokens)
-	costSavings := float64(tokenSavings) * avgCostPerToken
+	costSavings := p.promptTokenCost(log, tokenSavings)
 	monthlySavings := costSavings * 30 * 7 / p.config.TimeWindow.Hours() * 24
 
 	optimizedPrompt := p.generateOptimizedPrompt(prompt, "Replace uncertain language with clear, direct instructions.")
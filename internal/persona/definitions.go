@@ -0,0 +1,270 @@
+package persona
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Model tiers a persona may prefer. They name the provider model sizes the
+// dispatcher routes between.
+const (
+	TierSmall  = "small"
+	TierMedium = "medium"
+	TierLarge  = "large"
+	TierXLarge = "xlarge"
+)
+
+// personaNamePattern accepts lowercase, slash-separated names such as
+// default/qa-engineer
+var personaNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*(/[a-z0-9][a-z0-9_-]*)*$`)
+
+// Definition is the managed part of a persona. A published definition is
+// layered over the persona's SKILL.md, or stands alone for personas that
+// have none.
+type Definition struct {
+	Description    string   `json:"description,omitempty"`
+	SystemPrompt   string   `json:"system_prompt"`
+	AllowedActions []string `json:"allowed_actions,omitempty"` // Empty allows every action
+	ModelTier      string   `json:"model_tier,omitempty"`      // small, medium, large or xlarge; empty leaves it to the bead
+}
+
+// Version is one published definition of a persona. Versions are numbered
+// from 1 and never change; the highest is active. A rollback publishes a
+// copy of an older version.
+type Version struct {
+	Name           string     `json:"name"`
+	Version        int        `json:"version"`
+	Definition     Definition `json:"definition"`
+	Comment        string     `json:"comment,omitempty"`
+	RolledBackFrom int        `json:"rolled_back_from,omitempty"` // The version copied by a rollback
+	CreatedBy      string     `json:"created_by,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+// Store persists persona definition versions
+type Store interface {
+	// SavePersonaVersion inserts a new version; it fails if the number is taken
+	SavePersonaVersion(v *Version) error
+	// GetPersonaVersion returns one version, or nil if unknown. Version 0
+	// returns the latest.
+	GetPersonaVersion(name string, version int) (*Version, error)
+	// ListPersonaVersions returns a persona's versions, newest first
+	ListPersonaVersions(name string) ([]*Version, error)
+	// ListVersionedPersonas returns the names of personas with versions
+	ListVersionedPersonas() ([]string, error)
+	// DeletePersonaVersions removes every version of a persona
+	DeletePersonaVersions(name string) error
+}
+
+// ValidateName checks a persona name can be stored and addressed by the API
+func ValidateName(name string) error {
+	if !personaNamePattern.MatchString(name) {
+		return fmt.Errorf("persona name must be lowercase letters, digits, '-' and '_' in '/'-separated segments")
+	}
+	for _, seg := range strings.Split(name, "/") {
+		if seg == "versions" || seg == "rollback" {
+			return fmt.Errorf("persona name must not contain the segment %q", seg)
+		}
+	}
+	return nil
+}
+
+// normalize trims the definition and drops empty and repeated actions
+func (d *Definition) normalize() {
+	d.Description = strings.TrimSpace(d.Description)
+	d.SystemPrompt = strings.TrimSpace(d.SystemPrompt)
+	d.ModelTier = strings.ToLower(strings.TrimSpace(d.ModelTier))
+	seen := map[string]bool{}
+	actions := d.AllowedActions[:0]
+	for _, a := range d.AllowedActions {
+		a = strings.TrimSpace(a)
+		if a == "" || seen[a] {
+			continue
+		}
+		seen[a] = true
+		actions = append(actions, a)
+	}
+	d.AllowedActions = actions
+}
+
+// Validate checks the fields a definition needs
+func (d *Definition) Validate() error {
+	if strings.TrimSpace(d.SystemPrompt) == "" {
+		return fmt.Errorf("system_prompt must not be empty")
+	}
+	return ValidateModelTier(d.ModelTier)
+}
+
+// ValidateModelTier checks that tier is empty or one of the model tiers
+func ValidateModelTier(tier string) error {
+	switch tier {
+	case "", TierSmall, TierMedium, TierLarge, TierXLarge:
+		return nil
+	}
+	return fmt.Errorf("model_tier must be one of %s, %s, %s or %s", TierSmall, TierMedium, TierLarge, TierXLarge)
+}
+
+// AllowsAction reports whether a persona's agents may run an action type
+func AllowsAction(p *models.Persona, actionType string) bool {
+	if p == nil || len(p.AllowedActions) == 0 {
+		return true
+	}
+	for _, a := range p.AllowedActions {
+		if a == actionType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetStore keeps published persona definitions in store
+func (m *Manager) SetStore(store Store) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store = store
+	m.personas = make(map[string]*models.Persona)
+}
+
+// versionStore returns the definition store, or an error without one
+func (m *Manager) versionStore() (Store, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.store == nil {
+		return nil, fmt.Errorf("persona definitions need a store")
+	}
+	return m.store, nil
+}
+
+// Publish validates def and stores it as the persona's next version
+func (m *Manager) Publish(name string, def Definition, comment, createdBy string) (*Version, error) {
+	if err := ValidateName(name); err != nil {
+		return nil, err
+	}
+	def.normalize()
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return m.publish(&Version{Name: name, Definition: def, Comment: comment, CreatedBy: createdBy})
+}
+
+// Rollback makes an older version active again by publishing a copy of it
+func (m *Manager) Rollback(name string, version int, comment, createdBy string) (*Version, error) {
+	old, err := m.Version(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if comment == "" {
+		comment = fmt.Sprintf("Rollback to version %d", version)
+	}
+	return m.publish(&Version{
+		Name:           name,
+		Definition:     old.Definition,
+		Comment:        comment,
+		RolledBackFrom: version,
+		CreatedBy:      createdBy,
+	})
+}
+
+// publish numbers v after the persona's latest version and stores it
+func (m *Manager) publish(v *Version) (*Version, error) {
+	store, err := m.versionStore()
+	if err != nil {
+		return nil, err
+	}
+
+	m.publishMu.Lock()
+	defer m.publishMu.Unlock()
+	latest, err := store.GetPersonaVersion(v.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+	v.Version = 1
+	if latest != nil {
+		v.Version = latest.Version + 1
+	}
+	v.CreatedAt = time.Now().UTC()
+	if err := store.SavePersonaVersion(v); err != nil {
+		return nil, err
+	}
+	m.InvalidateCache(v.Name)
+	return v, nil
+}
+
+// Version returns one of a persona's published versions
+func (m *Manager) Version(name string, version int) (*Version, error) {
+	if version <= 0 {
+		return nil, fmt.Errorf("version must be a positive integer")
+	}
+	store, err := m.versionStore()
+	if err != nil {
+		return nil, err
+	}
+	v, err := store.GetPersonaVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("persona version not found: %s v%d", name, version)
+	}
+	return v, nil
+}
+
+// Versions returns a persona's published versions, newest first
+func (m *Manager) Versions(name string) ([]*Version, error) {
+	store, err := m.versionStore()
+	if err != nil {
+		return nil, err
+	}
+	list, err := store.ListPersonaVersions(name)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []*Version{}
+	}
+	return list, nil
+}
+
+// DeleteVersions removes a persona's published versions. A persona with a
+// SKILL.md falls back to it; one without is gone.
+func (m *Manager) DeleteVersions(name string) error {
+	store, err := m.versionStore()
+	if err != nil {
+		return err
+	}
+	if err := store.DeletePersonaVersions(name); err != nil {
+		return err
+	}
+	m.InvalidateCache(name)
+	return nil
+}
+
+// activeVersion returns the persona's latest published version, or nil
+func (m *Manager) activeVersion(name string) (*Version, error) {
+	m.mu.RLock()
+	store := m.store
+	m.mu.RUnlock()
+	if store == nil {
+		return nil, nil
+	}
+	return store.GetPersonaVersion(name, 0)
+}
+
+// apply layers a published version over p
+func (v *Version) apply(p *models.Persona) {
+	def := v.Definition
+	if def.Description != "" {
+		p.Description = def.Description
+		p.Character = def.Description
+	}
+	p.Instructions = def.SystemPrompt
+	p.Mission = def.SystemPrompt
+	p.AllowedActions = append([]string(nil), def.AllowedActions...)
+	p.ModelTier = def.ModelTier
+	p.DefinitionVersion = v.Version
+	p.UpdatedAt = v.CreatedAt
+}
@@ -0,0 +1,144 @@
+package persona_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/persona"
+)
+
+const testerSkillMd = `---
+name: tester
+description: A test persona for unit testing
+---
+
+## Instructions
+
+Test everything.
+`
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "persona.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPublish_LayersOverSkillMd(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tmpDir, "default", "tester"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(tmpDir, "default", "tester", "SKILL.md"), []byte(testerSkillMd), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := persona.NewManager(tmpDir)
+	m.SetStore(newTestDB(t))
+
+	before, err := m.LoadPersona("default/tester")
+	if err != nil {
+		t.Fatalf("LoadPersona failed: %v", err)
+	}
+	if before.DefinitionVersion != 0 || len(before.AllowedActions) != 0 {
+		t.Fatalf("expected the SKILL.md persona alone, got %+v", before)
+	}
+
+	v, err := m.Publish("default/tester", persona.Definition{
+		SystemPrompt:   "  Write tests first.  ",
+		AllowedActions: []string{"read_file", "run_tests", "read_file", " "},
+		ModelTier:      "Small",
+	}, "tighten tester", "admin")
+	if err != nil {
+		t.Fatalf("Publish failed: %v", err)
+	}
+	if v.Version != 1 || v.Definition.ModelTier != persona.TierSmall || len(v.Definition.AllowedActions) != 2 {
+		t.Fatalf("unexpected version %+v", v)
+	}
+
+	p, err := m.LoadPersona("default/tester")
+	if err != nil {
+		t.Fatalf("LoadPersona failed: %v", err)
+	}
+	if p.Instructions != "Write tests first." || p.Mission != p.Instructions || p.DefinitionVersion != 1 || p.ModelTier != persona.TierSmall {
+		t.Errorf("published definition not layered over SKILL.md: %+v", p)
+	}
+	if p.Description != "A test persona for unit testing" || p.PersonaFile == "" {
+		t.Errorf("expected SKILL.md fields to be kept, got %+v", p)
+	}
+	if !persona.AllowsAction(p, "run_tests") || persona.AllowsAction(p, "git_push") {
+		t.Errorf("unexpected allowed actions %v", p.AllowedActions)
+	}
+}
+
+func TestPublish_StandaloneRollbackAndDelete(t *testing.T) {
+	m := persona.NewManager(t.TempDir())
+	m.SetStore(newTestDB(t))
+
+	if _, err := m.Publish("team/reviewer", persona.Definition{SystemPrompt: "Review carefully.", ModelTier: persona.TierLarge}, "", "admin"); err != nil {
+		t.Fatalf("Publish v1 failed: %v", err)
+	}
+	if _, err := m.Publish("team/reviewer", persona.Definition{SystemPrompt: "Review quickly."}, "", "admin"); err != nil {
+		t.Fatalf("Publish v2 failed: %v", err)
+	}
+	if names, err := m.ListPersonas(); err != nil || len(names) != 1 || names[0] != "team/reviewer" {
+		t.Fatalf("expected the published persona to be listed, got %v (%v)", names, err)
+	}
+
+	v, err := m.Rollback("team/reviewer", 1, "", "admin")
+	if err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+	if v.Version != 3 || v.RolledBackFrom != 1 || !strings.Contains(v.Comment, "version 1") {
+		t.Errorf("unexpected rollback version %+v", v)
+	}
+	p, err := m.LoadPersona("team/reviewer")
+	if err != nil || p.Instructions != "Review carefully." || p.ModelTier != persona.TierLarge || p.DefinitionVersion != 3 {
+		t.Fatalf("rollback not active: %+v (%v)", p, err)
+	}
+	if versions, _ := m.Versions("team/reviewer"); len(versions) != 3 || versions[0].Version != 3 {
+		t.Errorf("expected three versions newest first, got %+v", versions)
+	}
+	if _, err := m.Rollback("team/reviewer", 9, "", "admin"); err == nil {
+		t.Error("expected rolling back to an unknown version to fail")
+	}
+
+	if err := m.DeleteVersions("team/reviewer"); err != nil {
+		t.Fatalf("DeleteVersions failed: %v", err)
+	}
+	if _, err := m.LoadPersona("team/reviewer"); err == nil {
+		t.Error("expected a persona without SKILL.md to be gone with its versions")
+	}
+}
+
+func TestPublish_Validation(t *testing.T) {
+	m := persona.NewManager(t.TempDir())
+	if _, err := m.Publish("qa", persona.Definition{SystemPrompt: "x"}, "", ""); err == nil {
+		t.Error("expected publishing without a store to fail")
+	}
+	m.SetStore(newTestDB(t))
+
+	tests := []struct {
+		name    string
+		persona string
+		def     persona.Definition
+	}{
+		{"empty prompt", "qa", persona.Definition{SystemPrompt: "  "}},
+		{"unknown tier", "qa", persona.Definition{SystemPrompt: "x", ModelTier: "huge"}},
+		{"uppercase name", "QA", persona.Definition{SystemPrompt: "x"}},
+		{"path traversal", "../qa", persona.Definition{SystemPrompt: "x"}},
+		{"reserved segment", "qa/versions", persona.Definition{SystemPrompt: "x"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.Publish(tt.persona, tt.def, "", ""); err == nil {
+				t.Errorf("expected %s to be rejected", tt.name)
+			}
+		})
+	}
+}
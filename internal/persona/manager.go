@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/jordanhubbard/loom/pkg/models"
@@ -18,7 +19,11 @@ import (
 // Manager handles persona loading, saving, and live editing
 type Manager struct {
 	personaDir string
-	personas   map[string]*models.Persona
+
+	mu        sync.RWMutex
+	personas  map[string]*models.Persona
+	store     Store      // Published definitions, nil for SKILL.md files only
+	publishMu sync.Mutex // Serializes version numbering
 }
 
 // NewManager creates a new persona manager
@@ -38,15 +43,49 @@ type SkillFrontmatter struct {
 	Metadata      map[string]interface{} `yaml:"metadata"`
 }
 
-// LoadPersona loads a persona from a directory (SKILL.md format)
+// LoadPersona loads a persona from a directory (SKILL.md format), with its
+// active published definition layered over it. A persona may also exist
+// only as a published definition.
 func (m *Manager) LoadPersona(name string) (*models.Persona, error) {
-	personaPath := filepath.Join(m.personaDir, name)
-
 	// Check if cached
-	if persona, ok := m.personas[name]; ok {
+	m.mu.RLock()
+	persona, ok := m.personas[name]
+	m.mu.RUnlock()
+	if ok {
 		return persona, nil
 	}
 
+	active, err := m.activeVersion(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load persona definition: %w", err)
+	}
+	persona, err = m.loadSkill(name)
+	if err != nil {
+		if active == nil {
+			return nil, err
+		}
+		persona = &models.Persona{
+			Name:          name,
+			AutonomyLevel: string(models.AutonomySemi),
+			CreatedAt:     active.CreatedAt,
+		}
+	}
+	if active != nil {
+		active.apply(persona)
+	}
+
+	// Cache it
+	m.mu.Lock()
+	m.personas[name] = persona
+	m.mu.Unlock()
+
+	return persona, nil
+}
+
+// loadSkill reads a persona's SKILL.md
+func (m *Manager) loadSkill(name string) (*models.Persona, error) {
+	personaPath := filepath.Join(m.personaDir, name)
+
 	// Load SKILL.md (Agent Skills format)
 	skillFile := filepath.Join(personaPath, "SKILL.md")
 	skillContent, err := os.ReadFile(skillFile)
@@ -92,9 +131,6 @@ func (m *Manager) LoadPersona(name string) (*models.Persona, error) {
 		}
 	}
 
-	// Cache it
-	m.personas[name] = persona
-
 	return persona, nil
 }
 
@@ -370,7 +406,7 @@ func (m *Manager) generateInstructionsContent(p *models.Persona) string {
 func (m *Manager) ListPersonas() ([]string, error) {
 	// Check if persona directory exists
 	if _, err := os.Stat(m.personaDir); os.IsNotExist(err) {
-		return []string{}, nil
+		return m.withVersioned([]string{})
 	}
 
 	var personas []string
@@ -402,6 +438,30 @@ func (m *Manager) ListPersonas() ([]string, error) {
 		return nil, err
 	}
 
+	return m.withVersioned(personas)
+}
+
+// withVersioned adds the personas that exist only as published definitions
+// to the names found on disk, sorted
+func (m *Manager) withVersioned(personas []string) ([]string, error) {
+	m.mu.RLock()
+	store := m.store
+	m.mu.RUnlock()
+	if store != nil {
+		versioned, err := store.ListVersionedPersonas()
+		if err != nil {
+			return nil, err
+		}
+		seen := make(map[string]bool, len(personas))
+		for _, name := range personas {
+			seen[name] = true
+		}
+		for _, name := range versioned {
+			if !seen[name] {
+				personas = append(personas, name)
+			}
+		}
+	}
 	sort.Strings(personas)
 	return personas, nil
 }
@@ -503,5 +563,7 @@ func copyFile(source, dest string) error {
 
 // InvalidateCache removes a persona from cache, forcing reload
 func (m *Manager) InvalidateCache(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	delete(m.personas, name)
 }
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"time"
 
 	"github.com/jordanhubbard/loom/pkg/plugin"
@@ -132,6 +133,33 @@ func (c *HTTPPluginClient) GetModels(ctx context.Context) ([]plugin.ModelInfo, e
 	return models, nil
 }
 
+// GetPanelData fetches a dashboard panel's data from GET /panels/{id}.
+func (c *HTTPPluginClient) GetPanelData(ctx context.Context, panelID string, params map[string]string) (*plugin.PanelData, error) {
+	path := "/panels/" + url.PathEscape(panelID)
+	if len(params) > 0 {
+		query := url.Values{}
+		for k, v := range params {
+			query.Set(k, v)
+		}
+		path += "?" + query.Encode()
+	}
+
+	resp, err := c.doRequest(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("panel request failed: %w", err)
+	}
+
+	var data plugin.PanelData
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, fmt.Errorf("failed to parse panel response: %w", err)
+	}
+	if data.Panel == "" {
+		data.Panel = panelID
+	}
+
+	return &data, nil
+}
+
 // Cleanup performs plugin cleanup.
 func (c *HTTPPluginClient) Cleanup(ctx context.Context) error {
 	_, err := c.doRequest(ctx, "POST", "/cleanup", nil)
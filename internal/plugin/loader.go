@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sync"
 
+	"github.com/jordanhubbard/loom/internal/audit"
 	"github.com/jordanhubbard/loom/pkg/plugin"
 	"gopkg.in/yaml.v3"
 )
@@ -16,6 +17,7 @@ import (
 type Loader struct {
 	pluginsDir string
 	plugins    map[string]*LoadedPlugin
+	panels     *PanelRegistry
 	mu         sync.RWMutex
 }
 
@@ -50,6 +52,9 @@ type PluginManifest struct {
 
 	// HealthCheckInterval is how often to check plugin health (seconds)
 	HealthCheckInterval int `json:"health_check_interval,omitempty" yaml:"health_check_interval,omitempty"`
+
+	// Panels are the dashboard panels the plugin serves
+	Panels []plugin.Panel `json:"panels,omitempty" yaml:"panels,omitempty"`
 }
 
 // NewLoader creates a new plugin loader.
@@ -60,6 +65,14 @@ func NewLoader(pluginsDir string) *Loader {
 	}
 }
 
+// SetPanelRegistry makes the loader register the dashboard panels of the
+// plugins it loads, namespaced by provider type.
+func (l *Loader) SetPanelRegistry(panels *PanelRegistry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.panels = panels
+}
+
 // DiscoverPlugins scans the plugins directory for plugin manifests.
 // Returns a list of discovered plugin manifests.
 func (l *Loader) DiscoverPlugins(ctx context.Context) ([]*PluginManifest, error) {
@@ -167,12 +180,34 @@ func (l *Loader) LoadPlugin(ctx context.Context, manifest *PluginManifest) error
 		return fmt.Errorf("plugin is unhealthy: %s", health.Message)
 	}
 
+	// Register dashboard panels
+	if len(manifest.Panels) > 0 && l.panels != nil {
+		source, ok := client.(plugin.PanelPlugin)
+		if !ok {
+			return fmt.Errorf("plugin declares panels but cannot serve panel data")
+		}
+		if err := l.panels.Register(manifest.Metadata.ProviderType, manifest.Panels, source); err != nil {
+			return fmt.Errorf("failed to register panels: %w", err)
+		}
+	}
+
 	// Store loaded plugin
 	l.plugins[manifest.Metadata.ProviderType] = &LoadedPlugin{
 		Manifest: manifest,
 		Client:   client,
 	}
 
+	audit.Record(audit.Event{
+		Actor:    "plugin-loader",
+		Action:   "plugin.load",
+		Resource: manifest.Metadata.ProviderType,
+		Details: map[string]interface{}{
+			"type":     manifest.Type,
+			"endpoint": manifest.Endpoint,
+			"version":  manifest.Metadata.Version,
+		},
+	})
+
 	return nil
 }
 
@@ -193,6 +228,15 @@ func (l *Loader) UnloadPlugin(ctx context.Context, providerType string) error {
 
 	// Remove from loaded plugins
 	delete(l.plugins, providerType)
+	if l.panels != nil {
+		l.panels.Unregister(providerType)
+	}
+
+	audit.Record(audit.Event{
+		Actor:    "plugin-loader",
+		Action:   "plugin.unload",
+		Resource: providerType,
+	})
 
 	return nil
 }
@@ -375,6 +419,18 @@ func ValidateManifest(manifest *PluginManifest) error {
 		return fmt.Errorf("unsupported plugin type: %s", manifest.Type)
 	}
 
+	seen := make(map[string]bool, len(manifest.Panels))
+	for i := range manifest.Panels {
+		panel := &manifest.Panels[i]
+		if err := panel.Validate(); err != nil {
+			return fmt.Errorf("panels: %w", err)
+		}
+		if seen[panel.ID] {
+			return fmt.Errorf("panels: duplicate panel id %s", panel.ID)
+		}
+		seen[panel.ID] = true
+	}
+
 	return nil
 }
 
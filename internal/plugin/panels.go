@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/plugin"
+)
+
+// namespacePattern restricts panel namespaces to URL path segments
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]*$`)
+
+// NamespacedPanel is a panel together with the plugin namespace it is served
+// under.
+type NamespacedPanel struct {
+	plugin.Panel
+	Plugin   string `json:"plugin"`
+	DataPath string `json:"data_path"`
+}
+
+// panelSource is one plugin's panels and the plugin that serves their data
+type panelSource struct {
+	panels []plugin.Panel
+	source plugin.PanelPlugin
+}
+
+// PanelRegistry holds the dashboard panels contributed by plugins. Each
+// plugin registers its panels under its own namespace, so panels of
+// different plugins never collide.
+type PanelRegistry struct {
+	mu         sync.RWMutex
+	namespaces map[string]*panelSource
+}
+
+// NewPanelRegistry creates an empty panel registry.
+func NewPanelRegistry() *PanelRegistry {
+	return &PanelRegistry{namespaces: make(map[string]*panelSource)}
+}
+
+// Register adds a plugin's panels under the given namespace, replacing any
+// panels the namespace had before.
+func (r *PanelRegistry) Register(namespace string, panels []plugin.Panel, source plugin.PanelPlugin) error {
+	if !namespacePattern.MatchString(namespace) {
+		return fmt.Errorf("invalid panel namespace %q", namespace)
+	}
+	if source == nil {
+		return fmt.Errorf("panel source is required")
+	}
+	seen := make(map[string]bool, len(panels))
+	for i := range panels {
+		if err := panels[i].Validate(); err != nil {
+			return err
+		}
+		if seen[panels[i].ID] {
+			return fmt.Errorf("duplicate panel id %s", panels[i].ID)
+		}
+		seen[panels[i].ID] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.namespaces[namespace] = &panelSource{
+		panels: append([]plugin.Panel(nil), panels...),
+		source: source,
+	}
+	return nil
+}
+
+// Unregister removes a namespace and its panels.
+func (r *PanelRegistry) Unregister(namespace string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.namespaces, namespace)
+}
+
+// List returns every registered panel, ordered by namespace and panel ID.
+func (r *PanelRegistry) List() []NamespacedPanel {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	namespaces := make([]string, 0, len(r.namespaces))
+	for ns := range r.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+
+	panels := []NamespacedPanel{}
+	for _, ns := range namespaces {
+		panels = append(panels, r.namespaced(ns)...)
+	}
+	return panels
+}
+
+// ListNamespace returns the panels of one plugin.
+func (r *PanelRegistry) ListNamespace(namespace string) ([]NamespacedPanel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.namespaces[namespace]; !ok {
+		return nil, fmt.Errorf("panel namespace not found: %s", namespace)
+	}
+	return r.namespaced(namespace), nil
+}
+
+func (r *PanelRegistry) namespaced(namespace string) []NamespacedPanel {
+	src := r.namespaces[namespace]
+	panels := make([]NamespacedPanel, 0, len(src.panels))
+	for _, p := range src.panels {
+		panels = append(panels, NamespacedPanel{
+			Panel:    p,
+			Plugin:   namespace,
+			DataPath: "/api/v1/plugins/" + namespace + "/panels/" + p.ID,
+		})
+	}
+	sort.Slice(panels, func(i, j int) bool { return panels[i].ID < panels[j].ID })
+	return panels
+}
+
+// Data fetches a panel's data from its plugin and checks it against the
+// panel's declared schema, so dashboards only ever see data of the shape the
+// plugin promised.
+func (r *PanelRegistry) Data(ctx context.Context, namespace, panelID string, params map[string]string) (*plugin.PanelData, error) {
+	r.mu.RLock()
+	src, ok := r.namespaces[namespace]
+	var panel *plugin.Panel
+	if ok {
+		for i := range src.panels {
+			if src.panels[i].ID == panelID {
+				panel = &src.panels[i]
+				break
+			}
+		}
+	}
+	r.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("panel namespace not found: %s", namespace)
+	}
+	if panel == nil {
+		return nil, fmt.Errorf("panel not found: %s/%s", namespace, panelID)
+	}
+
+	data, err := src.source.GetPanelData(ctx, panelID, params)
+	if err != nil {
+		return nil, fmt.Errorf("panel %s/%s: %w", namespace, panelID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("panel %s/%s: plugin returned no data", namespace, panelID)
+	}
+
+	// Round-trip through JSON so in-process plugins may return Go values
+	raw, err := json.Marshal(data.Data)
+	if err != nil {
+		return nil, fmt.Errorf("panel %s/%s: failed to encode data: %w", namespace, panelID, err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("panel %s/%s: failed to decode data: %w", namespace, panelID, err)
+	}
+	if err := plugin.ValidatePanelData(panel.Schema, decoded); err != nil {
+		return nil, fmt.Errorf("panel %s/%s: data does not match schema: %w", namespace, panelID, err)
+	}
+
+	out := &plugin.PanelData{Panel: panelID, Data: decoded, GeneratedAt: data.GeneratedAt}
+	if out.GeneratedAt.IsZero() {
+		out.GeneratedAt = time.Now().UTC()
+	}
+	return out, nil
+}
@@ -0,0 +1,199 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/plugin"
+)
+
+type staticPanels struct {
+	data interface{}
+	err  error
+}
+
+func (s *staticPanels) GetPanelData(ctx context.Context, panelID string, params map[string]string) (*plugin.PanelData, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &plugin.PanelData{Panel: panelID, Data: s.data}, nil
+}
+
+func queuePanel() plugin.Panel {
+	return plugin.Panel{
+		ID:    "queue",
+		Title: "Queue depth",
+		Schema: map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"depth"},
+			"properties": map[string]interface{}{
+				"depth": map[string]interface{}{"type": "integer"},
+			},
+		},
+	}
+}
+
+func TestPanelRegistry_RegisterAndList(t *testing.T) {
+	r := NewPanelRegistry()
+	if err := r.Register("metrics", []plugin.Panel{queuePanel()}, &staticPanels{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := r.Register("alpha", []plugin.Panel{queuePanel()}, &staticPanels{}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	panels := r.List()
+	if len(panels) != 2 {
+		t.Fatalf("expected 2 panels, got %d", len(panels))
+	}
+	if panels[0].Plugin != "alpha" || panels[1].Plugin != "metrics" {
+		t.Errorf("panels not ordered by namespace: %s, %s", panels[0].Plugin, panels[1].Plugin)
+	}
+	if panels[1].DataPath != "/api/v1/plugins/metrics/panels/queue" {
+		t.Errorf("DataPath = %q", panels[1].DataPath)
+	}
+
+	r.Unregister("alpha")
+	if _, err := r.ListNamespace("alpha"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found after Unregister, got %v", err)
+	}
+}
+
+func TestPanelRegistry_RegisterRejectsInvalid(t *testing.T) {
+	r := NewPanelRegistry()
+	noSchema := queuePanel()
+	noSchema.Schema = nil
+
+	tests := []struct {
+		name      string
+		namespace string
+		panels    []plugin.Panel
+	}{
+		{"bad namespace", "Bad/NS", []plugin.Panel{queuePanel()}},
+		{"missing schema", "metrics", []plugin.Panel{noSchema}},
+		{"duplicate id", "metrics", []plugin.Panel{queuePanel(), queuePanel()}},
+	}
+	for _, tt := range tests {
+		if err := r.Register(tt.namespace, tt.panels, &staticPanels{}); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestPanelRegistry_Data(t *testing.T) {
+	r := NewPanelRegistry()
+	source := &staticPanels{data: map[string]int{"depth": 7}}
+	if err := r.Register("metrics", []plugin.Panel{queuePanel()}, source); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	ctx := context.Background()
+
+	data, err := r.Data(ctx, "metrics", "queue", nil)
+	if err != nil {
+		t.Fatalf("Data: %v", err)
+	}
+	if data.Data.(map[string]interface{})["depth"] != float64(7) {
+		t.Errorf("unexpected data %v", data.Data)
+	}
+	if data.GeneratedAt.IsZero() {
+		t.Error("GeneratedAt should default to now")
+	}
+
+	if _, err := r.Data(ctx, "metrics", "missing", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected panel not found, got %v", err)
+	}
+	if _, err := r.Data(ctx, "other", "queue", nil); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected namespace not found, got %v", err)
+	}
+
+	source.data = map[string]string{"depth": "seven"}
+	if _, err := r.Data(ctx, "metrics", "queue", nil); err == nil || !strings.Contains(err.Error(), "schema") {
+		t.Errorf("expected schema mismatch, got %v", err)
+	}
+
+	source.err = fmt.Errorf("backend down")
+	if _, err := r.Data(ctx, "metrics", "queue", nil); err == nil || !strings.Contains(err.Error(), "backend down") {
+		t.Errorf("expected plugin error, got %v", err)
+	}
+}
+
+func TestLoadPlugin_HTTP_RegistersPanels(t *testing.T) {
+	metadata := plugin.Metadata{
+		Name:         "Metrics Plugin",
+		Version:      "1.0.0",
+		ProviderType: "metrics",
+	}
+
+	var gotQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/metadata":
+			json.NewEncoder(w).Encode(metadata)
+		case "/health":
+			json.NewEncoder(w).Encode(plugin.HealthStatus{Healthy: true, Timestamp: time.Now()})
+		case "/panels/queue":
+			gotQuery = r.URL.Query().Get("project_id")
+			fmt.Fprint(w, `{"panel":"queue","data":{"depth":3}}`)
+		default:
+			fmt.Fprint(w, `{}`)
+		}
+	}))
+	defer server.Close()
+
+	panels := NewPanelRegistry()
+	loader := NewLoader(t.TempDir())
+	loader.SetPanelRegistry(panels)
+	ctx := context.Background()
+
+	manifest := &PluginManifest{
+		Type:     "http",
+		Endpoint: server.URL,
+		Metadata: &metadata,
+		Panels:   []plugin.Panel{queuePanel()},
+	}
+	if err := loader.LoadPlugin(ctx, manifest); err != nil {
+		t.Fatalf("LoadPlugin: %v", err)
+	}
+
+	data, err := panels.Data(ctx, "metrics", "queue", map[string]string{"project_id": "proj-1"})
+	if err != nil {
+		t.Fatalf("Data: %v", err)
+	}
+	if gotQuery != "proj-1" {
+		t.Errorf("plugin got project_id %q, want proj-1", gotQuery)
+	}
+	if data.Data.(map[string]interface{})["depth"] != float64(3) {
+		t.Errorf("unexpected data %v", data.Data)
+	}
+
+	if err := loader.UnloadPlugin(ctx, "metrics"); err != nil {
+		t.Fatalf("UnloadPlugin: %v", err)
+	}
+	if len(panels.List()) != 0 {
+		t.Error("panels should be unregistered with the plugin")
+	}
+}
+
+func TestValidateManifest_Panels(t *testing.T) {
+	manifest := &PluginManifest{
+		Type:     "http",
+		Endpoint: "http://localhost:8090",
+		Metadata: &plugin.Metadata{Name: "m", Version: "1.0.0", ProviderType: "metrics"},
+		Panels:   []plugin.Panel{queuePanel(), queuePanel()},
+	}
+	if err := ValidateManifest(manifest); err == nil || !strings.Contains(err.Error(), "duplicate") {
+		t.Errorf("expected duplicate panel error, got %v", err)
+	}
+
+	manifest.Panels = []plugin.Panel{queuePanel()}
+	if err := ValidateManifest(manifest); err != nil {
+		t.Errorf("ValidateManifest: %v", err)
+	}
+}
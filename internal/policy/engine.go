@@ -0,0 +1,314 @@
+package policy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Input is the question put to the engine: the kind of policy to apply,
+// the project it concerns and the attributes rules match on, such as
+// action, agent_role, bead_priority, dispatch_count or project_cost_today_usd
+type Input struct {
+	Kind       string                 `json:"kind"`
+	ProjectID  string                 `json:"project_id,omitempty"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// Sources of a decision
+const (
+	SourceProject = "project" // A rule or default of the project's active version
+	SourceGlobal  = "global"  // A rule or default of the global policy file
+	SourceBuiltin = "builtin" // No policy said anything
+)
+
+// RuleTrace records whether one rule matched during an evaluation
+type RuleTrace struct {
+	RuleID  string `json:"rule_id"`
+	Source  string `json:"source"`
+	Matched bool   `json:"matched"`
+}
+
+// Decision is the engine's answer
+type Decision struct {
+	Kind          string      `json:"kind"`
+	Effect        string      `json:"effect"`
+	Reason        string      `json:"reason,omitempty"`
+	RuleID        string      `json:"rule_id,omitempty"` // Empty when a default decided
+	Source        string      `json:"source"`
+	PolicyVersion int         `json:"policy_version,omitempty"` // The project version consulted, 0 for none
+	Trace         []RuleTrace `json:"trace,omitempty"`
+}
+
+// Version is one published policy of a project. Versions are numbered from
+// 1 and never change; the highest is active.
+type Version struct {
+	ProjectID string    `json:"project_id"`
+	Version   int       `json:"version"`
+	Document  Document  `json:"document"`
+	Comment   string    `json:"comment,omitempty"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Store persists project policy versions
+type Store interface {
+	// SavePolicyVersion inserts a new version; it fails if the number is taken
+	SavePolicyVersion(v *Version) error
+	// GetPolicyVersion returns one version, or nil if unknown. Version 0
+	// returns the latest.
+	GetPolicyVersion(projectID string, version int) (*Version, error)
+	// ListPolicyVersions returns a project's versions, newest first
+	ListPolicyVersions(projectID string) ([]*Version, error)
+}
+
+// Engine evaluates policies. A nil Engine allows everything and never
+// escalates.
+type Engine struct {
+	store Store
+
+	mu     sync.RWMutex
+	global *Document
+	active map[string]*Version // Latest version per project, nil when none
+}
+
+// ErrNoStore is returned for project policy versions when the engine has no
+// store
+var ErrNoStore = errors.New("project policies need a database")
+
+// NewEngine creates an engine over the project versions in store and the
+// global policy document, either of which may be nil. Without a store only
+// the global policy and the built-in defaults apply.
+func NewEngine(store Store, global *Document) *Engine {
+	return &Engine{store: store, global: global, active: make(map[string]*Version)}
+}
+
+// LoadFile reads a global policy document from a YAML or JSON file
+func LoadFile(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Global returns the global policy document, or nil
+func (e *Engine) Global() *Document {
+	if e == nil {
+		return nil
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.global
+}
+
+// SetGlobal replaces the global policy document, e.g. after the file changed
+func (e *Engine) SetGlobal(doc *Document) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.global = doc
+}
+
+// Publish validates doc and stores it as the project's next version
+func (e *Engine) Publish(projectID string, doc Document, comment, createdBy string) (*Version, error) {
+	if projectID == "" {
+		return nil, fmt.Errorf("project_id must not be empty")
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	if e.store == nil {
+		return nil, ErrNoStore
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	latest, err := e.store.GetPolicyVersion(projectID, 0)
+	if err != nil {
+		return nil, err
+	}
+	v := &Version{
+		ProjectID: projectID,
+		Version:   1,
+		Document:  doc,
+		Comment:   comment,
+		CreatedBy: createdBy,
+		CreatedAt: time.Now().UTC(),
+	}
+	if latest != nil {
+		v.Version = latest.Version + 1
+	}
+	if err := e.store.SavePolicyVersion(v); err != nil {
+		return nil, err
+	}
+	e.active[projectID] = v
+	return v, nil
+}
+
+// Active returns the project's active policy version, or nil when it has
+// never published one
+func (e *Engine) Active(projectID string) (*Version, error) {
+	if e == nil || e.store == nil || projectID == "" {
+		return nil, nil
+	}
+	e.mu.RLock()
+	v, cached := e.active[projectID]
+	e.mu.RUnlock()
+	if cached {
+		return v, nil
+	}
+
+	v, err := e.store.GetPolicyVersion(projectID, 0)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.active[projectID] = v
+	e.mu.Unlock()
+	return v, nil
+}
+
+// Version returns one of a project's policy versions
+func (e *Engine) Version(projectID string, version int) (*Version, error) {
+	if version <= 0 {
+		return nil, fmt.Errorf("version must be a positive integer")
+	}
+	if e.store == nil {
+		return nil, ErrNoStore
+	}
+	v, err := e.store.GetPolicyVersion(projectID, version)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, fmt.Errorf("policy version not found: %s v%d", projectID, version)
+	}
+	return v, nil
+}
+
+// Versions returns a project's policy versions, newest first
+func (e *Engine) Versions(projectID string) ([]*Version, error) {
+	if e.store == nil {
+		return nil, ErrNoStore
+	}
+	return e.store.ListPolicyVersions(projectID)
+}
+
+// Governs reports whether the project's active version or the global policy
+// has any rule or default of the kind, so callers can skip gathering
+// expensive attributes for kinds nobody configured
+func (e *Engine) Governs(projectID, kind string) bool {
+	if e == nil {
+		return false
+	}
+	v, _ := e.Active(projectID)
+	if v != nil && v.Document.governs(kind) {
+		return true
+	}
+	global := e.Global()
+	return global != nil && global.governs(kind)
+}
+
+func (d *Document) governs(kind string) bool {
+	if _, ok := d.Defaults[kind]; ok {
+		return true
+	}
+	for _, r := range d.Rules {
+		if r.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluate decides in against the project's active version, then the
+// global policy, then the built-in default of the kind. Evaluation errors
+// fall back to the global policy so a broken store cannot stop dispatch.
+func (e *Engine) Evaluate(ctx context.Context, in Input) Decision {
+	if e == nil {
+		return evaluate(in, nil, 0, nil)
+	}
+	v, _ := e.Active(in.ProjectID)
+	var project *Document
+	version := 0
+	if v != nil {
+		project, version = &v.Document, v.Version
+	}
+	return evaluate(in, project, version, e.Global())
+}
+
+// DryRun evaluates in without side effects. A non-nil draft is evaluated in
+// place of the project's active version, so a policy can be tried before it
+// is published.
+func (e *Engine) DryRun(ctx context.Context, in Input, draft *Document) (Decision, error) {
+	if _, ok := kindEffects[in.Kind]; !ok {
+		return Decision{}, fmt.Errorf("invalid kind %q: must be one of %s", in.Kind, strings.Join(Kinds(), ", "))
+	}
+	if draft == nil {
+		return e.Evaluate(ctx, in), nil
+	}
+	if err := draft.Validate(); err != nil {
+		return Decision{}, err
+	}
+	return evaluate(in, draft, 0, e.Global()), nil
+}
+
+// evaluate applies the first matching rule of the project document, then of
+// the global document, and otherwise the most specific default
+func evaluate(in Input, project *Document, version int, global *Document) Decision {
+	d := Decision{Kind: in.Kind, PolicyVersion: version}
+	layers := []struct {
+		source string
+		doc    *Document
+	}{{SourceProject, project}, {SourceGlobal, global}}
+
+	for _, layer := range layers {
+		if layer.doc == nil {
+			continue
+		}
+		for _, r := range layer.doc.Rules {
+			if r.Kind != in.Kind {
+				continue
+			}
+			matched := true
+			for _, c := range r.When {
+				if !c.matches(in.Attributes) {
+					matched = false
+					break
+				}
+			}
+			d.Trace = append(d.Trace, RuleTrace{RuleID: r.ID, Source: layer.source, Matched: matched})
+			if matched {
+				d.Effect, d.RuleID, d.Source = r.Effect, r.ID, layer.source
+				d.Reason = r.Reason
+				if d.Reason == "" {
+					d.Reason = fmt.Sprintf("%s policy rule %s", in.Kind, r.ID)
+				}
+				return d
+			}
+		}
+	}
+
+	for _, layer := range layers {
+		if layer.doc == nil {
+			continue
+		}
+		if effect, ok := layer.doc.Defaults[in.Kind]; ok {
+			d.Effect, d.Source = effect, layer.source
+			d.Reason = fmt.Sprintf("%s default of the %s policy", in.Kind, layer.source)
+			return d
+		}
+	}
+
+	d.Source = SourceBuiltin
+	if effects, ok := kindEffects[in.Kind]; ok {
+		d.Effect = effects[0]
+	} else {
+		d.Effect = EffectAllow
+	}
+	return d
+}
@@ -0,0 +1,260 @@
+// Package policy is the arbiter's policy engine. Escalation, approval,
+// budget, tool-permission and plan-approval policies are declared as
+// ordered rules in a YAML or JSON document: each rule names its kind, the
+// conditions it matches on and the effect it has, and the first matching
+// rule of a kind decides. Each project publishes its own numbered policy versions on top of
+// a global policy file, and any document can be evaluated as a dry run.
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Policy kinds
+const (
+	KindEscalation     = "escalation"      // A bead over the dispatch limit and stuck in a loop
+	KindApproval       = "approval"        // An agent action that may need a human's approval
+	KindBudget         = "budget"          // Dispatching a bead given what its project has spent
+	KindToolPermission = "tool_permission" // An agent running an action at all
+	KindPlanApproval   = "plan_approval"   // An execution plan that may run without a human's approval
+)
+
+// Effects
+const (
+	EffectAllow           = "allow"
+	EffectDeny            = "deny"
+	EffectRequireApproval = "require_approval"
+	EffectEscalate        = "escalate"
+	EffectBlock           = "block"
+)
+
+// Condition operators
+const (
+	OpEq       = "eq"
+	OpNe       = "ne"
+	OpIn       = "in"
+	OpNotIn    = "not_in"
+	OpGt       = "gt"
+	OpGte      = "gte"
+	OpLt       = "lt"
+	OpLte      = "lte"
+	OpMatches  = "matches"  // Shell glob, e.g. "git_*" or "docs/*.md"
+	OpContains = "contains" // Substring
+	OpExists   = "exists"   // The attribute is present and not empty
+)
+
+// kindEffects lists the effects each kind allows; the first is the
+// built-in default when no rule matches
+var kindEffects = map[string][]string{
+	KindEscalation:     {EffectBlock, EffectEscalate},
+	KindApproval:       {EffectAllow, EffectRequireApproval},
+	KindBudget:         {EffectAllow, EffectDeny},
+	KindToolPermission: {EffectAllow, EffectDeny},
+	KindPlanApproval:   {EffectRequireApproval, EffectAllow},
+}
+
+// Kinds returns the policy kinds in a stable order
+func Kinds() []string {
+	return []string{KindEscalation, KindApproval, KindBudget, KindToolPermission, KindPlanApproval}
+}
+
+var ruleIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Condition compares one input attribute with a value
+type Condition struct {
+	Field string      `json:"field" yaml:"field"`
+	Op    string      `json:"op" yaml:"op"`
+	Value interface{} `json:"value,omitempty" yaml:"value,omitempty"`
+}
+
+// Rule applies its effect to inputs of its kind that match every condition
+type Rule struct {
+	ID          string      `json:"id" yaml:"id"`
+	Kind        string      `json:"kind" yaml:"kind"`
+	Description string      `json:"description,omitempty" yaml:"description,omitempty"`
+	When        []Condition `json:"when,omitempty" yaml:"when,omitempty"` // Empty matches everything
+	Effect      string      `json:"effect" yaml:"effect"`
+	Reason      string      `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// Document is a policy file: ordered rules and, per kind, the effect when
+// none of them match
+type Document struct {
+	Rules    []Rule            `json:"rules" yaml:"rules"`
+	Defaults map[string]string `json:"defaults,omitempty" yaml:"defaults,omitempty"`
+}
+
+// Parse reads a policy document from YAML or JSON
+func Parse(data []byte) (*Document, error) {
+	var doc Document
+	trimmed := strings.TrimSpace(string(data))
+	var err error
+	if strings.HasPrefix(trimmed, "{") {
+		err = json.Unmarshal(data, &doc)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy document: %w", err)
+	}
+	if err := doc.Validate(); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Validate checks every rule and default of the document
+func (d *Document) Validate() error {
+	seen := make(map[string]bool, len(d.Rules))
+	for i, r := range d.Rules {
+		if !ruleIDPattern.MatchString(r.ID) {
+			return fmt.Errorf("rule %d: invalid id %q", i+1, r.ID)
+		}
+		if seen[r.ID] {
+			return fmt.Errorf("rule %s: duplicate id", r.ID)
+		}
+		seen[r.ID] = true
+		if err := validateEffect(r.Kind, r.Effect); err != nil {
+			return fmt.Errorf("rule %s: %w", r.ID, err)
+		}
+		for j, c := range r.When {
+			if err := c.validate(); err != nil {
+				return fmt.Errorf("rule %s condition %d: %w", r.ID, j+1, err)
+			}
+		}
+	}
+	for kind, effect := range d.Defaults {
+		if err := validateEffect(kind, effect); err != nil {
+			return fmt.Errorf("defaults: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateEffect(kind, effect string) error {
+	effects, ok := kindEffects[kind]
+	if !ok {
+		return fmt.Errorf("invalid kind %q: must be one of %s", kind, strings.Join(Kinds(), ", "))
+	}
+	for _, e := range effects {
+		if e == effect {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid effect %q for %s: must be one of %s", effect, kind, strings.Join(effects, ", "))
+}
+
+func (c Condition) validate() error {
+	if c.Field == "" {
+		return fmt.Errorf("field must not be empty")
+	}
+	switch c.Op {
+	case OpEq, OpNe, OpContains:
+		if c.Value == nil {
+			return fmt.Errorf("%s needs a value", c.Op)
+		}
+	case OpIn, OpNotIn:
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Errorf("%s needs a list value", c.Op)
+		}
+	case OpGt, OpGte, OpLt, OpLte:
+		if _, ok := toFloat(c.Value); !ok {
+			return fmt.Errorf("%s needs a numeric value", c.Op)
+		}
+	case OpMatches:
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return fmt.Errorf("matches needs a glob pattern")
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %w", pattern, err)
+		}
+	case OpExists:
+	default:
+		return fmt.Errorf("invalid op %q", c.Op)
+	}
+	return nil
+}
+
+// matches reports whether the condition holds for the attributes
+func (c Condition) matches(attrs map[string]interface{}) bool {
+	v, present := attrs[c.Field]
+	if c.Op == OpExists {
+		return present && v != nil && fmt.Sprint(v) != ""
+	}
+	if !present {
+		// A missing attribute only satisfies negative conditions
+		return c.Op == OpNe || c.Op == OpNotIn
+	}
+
+	switch c.Op {
+	case OpEq:
+		return equal(v, c.Value)
+	case OpNe:
+		return !equal(v, c.Value)
+	case OpIn, OpNotIn:
+		found := false
+		for _, item := range c.Value.([]interface{}) {
+			if equal(v, item) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Op == OpIn)
+	case OpGt, OpGte, OpLt, OpLte:
+		a, ok := toFloat(v)
+		if !ok {
+			return false
+		}
+		b, _ := toFloat(c.Value)
+		switch c.Op {
+		case OpGt:
+			return a > b
+		case OpGte:
+			return a >= b
+		case OpLt:
+			return a < b
+		}
+		return a <= b
+	case OpMatches:
+		ok, _ := path.Match(c.Value.(string), fmt.Sprint(v))
+		return ok
+	case OpContains:
+		return strings.Contains(fmt.Sprint(v), fmt.Sprint(c.Value))
+	}
+	return false
+}
+
+// equal compares numbers numerically and everything else as text
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return x == y
+		}
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,268 @@
+package policy_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/policy"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "policy.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+const projectPolicy = `
+rules:
+  - id: no-force-push
+    kind: tool_permission
+    when:
+      - {field: action, op: eq, value: run_command}
+      - {field: command, op: contains, value: "push --force"}
+    effect: deny
+    reason: force pushes are not allowed
+  - id: approve-merges
+    kind: approval
+    when:
+      - {field: action, op: matches, value: "git_merge*"}
+      - {field: agent_role, op: not_in, value: [CEO, Engineering Manager]}
+    effect: require_approval
+  - id: daily-cap
+    kind: budget
+    when:
+      - {field: project_cost_today_usd, op: gte, value: 25}
+      - {field: bead_priority, op: gt, value: 0}
+    effect: deny
+defaults:
+  escalation: escalate
+`
+
+func TestParse(t *testing.T) {
+	doc, err := policy.Parse([]byte(projectPolicy))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(doc.Rules) != 3 || doc.Defaults[policy.KindEscalation] != policy.EffectEscalate {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+
+	if _, err := policy.Parse([]byte(`{"rules":[{"id":"r","kind":"budget","effect":"deny"}]}`)); err != nil {
+		t.Errorf("Parse JSON: %v", err)
+	}
+}
+
+func TestValidateRejects(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"unknown kind", `rules: [{id: r, kind: spend, effect: deny}]`},
+		{"effect of another kind", `rules: [{id: r, kind: budget, effect: escalate}]`},
+		{"bad id", `rules: [{id: "r 1", kind: budget, effect: deny}]`},
+		{"duplicate id", `rules: [{id: r, kind: budget, effect: deny}, {id: r, kind: budget, effect: allow}]`},
+		{"unknown op", `rules: [{id: r, kind: budget, effect: deny, when: [{field: x, op: like, value: y}]}]`},
+		{"non-numeric gt", `rules: [{id: r, kind: budget, effect: deny, when: [{field: x, op: gt, value: lots}]}]`},
+		{"in without list", `rules: [{id: r, kind: budget, effect: deny, when: [{field: x, op: in, value: y}]}]`},
+		{"bad default", `defaults: {approval: deny}`},
+	}
+	for _, tt := range tests {
+		if _, err := policy.Parse([]byte(tt.src)); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	doc, err := policy.Parse([]byte(projectPolicy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := policy.NewEngine(newTestDB(t), nil)
+	if _, err := engine.Publish("proj-1", *doc, "initial", "u-1"); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		in         policy.Input
+		wantEffect string
+		wantRule   string
+		wantSource string
+	}{
+		{"force push denied",
+			policy.Input{Kind: policy.KindToolPermission, ProjectID: "proj-1", Attributes: map[string]interface{}{"action": "run_command", "command": "git push --force origin main"}},
+			policy.EffectDeny, "no-force-push", policy.SourceProject},
+		{"plain push allowed",
+			policy.Input{Kind: policy.KindToolPermission, ProjectID: "proj-1", Attributes: map[string]interface{}{"action": "run_command", "command": "git push origin main"}},
+			policy.EffectAllow, "", policy.SourceBuiltin},
+		{"engineer merge needs approval",
+			policy.Input{Kind: policy.KindApproval, ProjectID: "proj-1", Attributes: map[string]interface{}{"action": "git_merge", "agent_role": "Engineer"}},
+			policy.EffectRequireApproval, "approve-merges", policy.SourceProject},
+		{"CEO merge allowed",
+			policy.Input{Kind: policy.KindApproval, ProjectID: "proj-1", Attributes: map[string]interface{}{"action": "git_merge", "agent_role": "CEO"}},
+			policy.EffectAllow, "", policy.SourceBuiltin},
+		{"over budget",
+			policy.Input{Kind: policy.KindBudget, ProjectID: "proj-1", Attributes: map[string]interface{}{"project_cost_today_usd": 30.5, "bead_priority": 2}},
+			policy.EffectDeny, "daily-cap", policy.SourceProject},
+		{"P0 exempt from budget",
+			policy.Input{Kind: policy.KindBudget, ProjectID: "proj-1", Attributes: map[string]interface{}{"project_cost_today_usd": 30.5, "bead_priority": 0}},
+			policy.EffectAllow, "", policy.SourceBuiltin},
+		{"escalation default",
+			policy.Input{Kind: policy.KindEscalation, ProjectID: "proj-1"},
+			policy.EffectEscalate, "", policy.SourceProject},
+		{"other project uses builtin",
+			policy.Input{Kind: policy.KindEscalation, ProjectID: "proj-2"},
+			policy.EffectBlock, "", policy.SourceBuiltin},
+	}
+	for _, tt := range tests {
+		d := engine.Evaluate(ctx, tt.in)
+		if d.Effect != tt.wantEffect || d.RuleID != tt.wantRule || d.Source != tt.wantSource {
+			t.Errorf("%s: got (%s, %q, %s), want (%s, %q, %s)", tt.name, d.Effect, d.RuleID, d.Source, tt.wantEffect, tt.wantRule, tt.wantSource)
+		}
+	}
+
+	d := engine.Evaluate(ctx, tests[0].in)
+	if d.PolicyVersion != 1 || len(d.Trace) != 1 || !d.Trace[0].Matched {
+		t.Errorf("unexpected version or trace: %+v", d)
+	}
+	if d.Reason != "force pushes are not allowed" {
+		t.Errorf("Reason = %q", d.Reason)
+	}
+}
+
+func TestEvaluate_ProjectOverridesGlobal(t *testing.T) {
+	global, err := policy.Parse([]byte(`
+rules:
+  - id: no-shell
+    kind: tool_permission
+    when: [{field: action, op: eq, value: run_command}]
+    effect: deny
+defaults:
+  budget: deny
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := policy.NewEngine(newTestDB(t), global)
+	ctx := context.Background()
+	in := policy.Input{Kind: policy.KindToolPermission, ProjectID: "proj-1", Attributes: map[string]interface{}{"action": "run_command"}}
+
+	if d := engine.Evaluate(ctx, in); d.Effect != policy.EffectDeny || d.Source != policy.SourceGlobal {
+		t.Errorf("global rule: got %+v", d)
+	}
+	if d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindBudget, ProjectID: "proj-1"}); d.Effect != policy.EffectDeny || d.Source != policy.SourceGlobal {
+		t.Errorf("global default: got %+v", d)
+	}
+
+	override := policy.Document{Rules: []policy.Rule{{
+		ID: "shell-ok", Kind: policy.KindToolPermission, Effect: policy.EffectAllow,
+		When: []policy.Condition{{Field: "action", Op: policy.OpEq, Value: "run_command"}},
+	}}}
+	if _, err := engine.Publish("proj-1", override, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	d := engine.Evaluate(ctx, in)
+	if d.Effect != policy.EffectAllow || d.Source != policy.SourceProject {
+		t.Errorf("project rule: got %+v", d)
+	}
+	if !engine.Governs("proj-1", policy.KindBudget) || engine.Governs("proj-2", policy.KindApproval) {
+		t.Error("Governs should see the global budget default and no approval policy")
+	}
+}
+
+func TestPublishVersions(t *testing.T) {
+	engine := policy.NewEngine(newTestDB(t), nil)
+	for i := 0; i < 3; i++ {
+		if _, err := engine.Publish("proj-1", policy.Document{}, "", ""); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+	versions, err := engine.Versions("proj-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 3 || versions[0].Version != 3 || versions[2].Version != 1 {
+		t.Fatalf("versions not numbered newest first: %+v", versions)
+	}
+	if _, err := engine.Version("proj-1", 4); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected not found, got %v", err)
+	}
+	if _, err := engine.Publish("proj-1", policy.Document{Defaults: map[string]string{"budget": "escalate"}}, "", ""); err == nil {
+		t.Error("Publish should validate the document")
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	engine := policy.NewEngine(nil, nil)
+	ctx := context.Background()
+	in := policy.Input{Kind: policy.KindBudget, ProjectID: "proj-1", Attributes: map[string]interface{}{"project_cost_today_usd": 12}}
+
+	draft := &policy.Document{Rules: []policy.Rule{{
+		ID: "cap", Kind: policy.KindBudget, Effect: policy.EffectDeny,
+		When: []policy.Condition{{Field: "project_cost_today_usd", Op: policy.OpGt, Value: 10}},
+	}}}
+	d, err := engine.DryRun(ctx, in, draft)
+	if err != nil {
+		t.Fatalf("DryRun: %v", err)
+	}
+	if d.Effect != policy.EffectDeny || d.RuleID != "cap" {
+		t.Errorf("draft not applied: %+v", d)
+	}
+	if live := engine.Evaluate(ctx, in); live.Effect != policy.EffectAllow {
+		t.Errorf("dry run leaked into live policy: %+v", live)
+	}
+	if _, err := engine.DryRun(ctx, policy.Input{Kind: "spend"}, nil); err == nil {
+		t.Error("expected invalid kind error")
+	}
+}
+
+func TestEngineWithoutStore(t *testing.T) {
+	global := &policy.Document{Defaults: map[string]string{policy.KindBudget: policy.EffectDeny}}
+	engine := policy.NewEngine(nil, global)
+	if _, err := engine.Publish("proj-1", policy.Document{}, "", ""); !errors.Is(err, policy.ErrNoStore) {
+		t.Errorf("Publish without a store: got %v", err)
+	}
+	if _, err := engine.Versions("proj-1"); !errors.Is(err, policy.ErrNoStore) {
+		t.Errorf("Versions without a store: got %v", err)
+	}
+	if d := engine.Evaluate(context.Background(), policy.Input{Kind: policy.KindBudget, ProjectID: "proj-1"}); d.Effect != policy.EffectDeny || d.Source != policy.SourceGlobal {
+		t.Errorf("expected the global policy to apply, got %+v", d)
+	}
+}
+
+func TestEvaluate_PlanApproval(t *testing.T) {
+	doc, err := policy.Parse([]byte(`
+rules:
+  - id: small-plans
+    kind: plan_approval
+    when:
+      - {field: plan_cost_usd, op: lt, value: 0.5}
+      - {field: plan_command_text, op: not_in, value: ["make deploy"]}
+    effect: allow
+    reason: small plans run without review
+`))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	engine := policy.NewEngine(nil, doc)
+	ctx := context.Background()
+
+	d := engine.Evaluate(ctx, policy.Input{Kind: policy.KindPlanApproval, ProjectID: "proj-1", Attributes: map[string]interface{}{"plan_cost_usd": 0.1, "plan_command_text": "go test ./..."}})
+	if d.Effect != policy.EffectAllow || d.RuleID != "small-plans" {
+		t.Errorf("expected the small plan approved, got %+v", d)
+	}
+	d = engine.Evaluate(ctx, policy.Input{Kind: policy.KindPlanApproval, ProjectID: "proj-1", Attributes: map[string]interface{}{"plan_cost_usd": 3.0}})
+	if d.Effect != policy.EffectRequireApproval || d.Source != policy.SourceBuiltin {
+		t.Errorf("expected a large plan to need approval, got %+v", d)
+	}
+}
@@ -0,0 +1,474 @@
+// Package pricing keeps the per-model token prices that requests are
+// costed at. Prices come from a bundled dataset, from configuration and
+// from edits made through the API; a periodic refresh reloads the first
+// two without touching the edits.
+package pricing
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Price sources, in increasing precedence
+const (
+	SourceBundled = "bundled" // From the bundled dataset, or the file replacing it
+	SourceConfig  = "config"  // From the models.metadata configuration
+	SourceManual  = "manual"  // Set through the API; refreshes leave it alone
+)
+
+// Price is what a model charges per million tokens
+type Price struct {
+	ProviderID           string    `json:"provider_id,omitempty"` // Empty prices the model on any provider
+	Model                string    `json:"model"`
+	InputPerMToken       float64   `json:"input_per_mtoken"`
+	OutputPerMToken      float64   `json:"output_per_mtoken"`
+	CachedInputPerMToken float64   `json:"cached_input_per_mtoken,omitempty"` // Prompt tokens read from the provider's prompt cache; 0 charges the input price
+	Source               string    `json:"source"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
+
+// Cost returns the price in USD of a request. cachedTokens are the prompt
+// tokens, out of promptTokens, that the provider served from its cache.
+func (p *Price) Cost(promptTokens, completionTokens, cachedTokens int64) float64 {
+	cached := p.CachedInputPerMToken
+	if cached == 0 {
+		cached = p.InputPerMToken
+	}
+	if cachedTokens > promptTokens {
+		cachedTokens = promptTokens
+	}
+	return (float64(promptTokens-cachedTokens)*p.InputPerMToken +
+		float64(cachedTokens)*cached +
+		float64(completionTokens)*p.OutputPerMToken) / 1e6
+}
+
+// Validate checks that a price is well formed
+func (p *Price) Validate() error {
+	if strings.TrimSpace(p.Model) == "" {
+		return fmt.Errorf("model is required")
+	}
+	if p.InputPerMToken < 0 || p.OutputPerMToken < 0 || p.CachedInputPerMToken < 0 {
+		return fmt.Errorf("model %s: prices must not be negative", p.Model)
+	}
+	return nil
+}
+
+// Store persists prices
+type Store interface {
+	// SaveModelPrice inserts or replaces the price of a provider's model
+	SaveModelPrice(p *Price) error
+	// ListModelPrices returns every price
+	ListModelPrices() ([]*Price, error)
+	// DeleteModelPrice removes the price of a provider's model
+	DeleteModelPrice(providerID, model string) error
+}
+
+// Config tunes the catalog
+type Config struct {
+	Interval    time.Duration // Between refreshes; default 24h
+	DatasetPath string        // A JSON dataset replacing the bundled one; reread on each refresh
+	Overrides   []Price       // Prices from configuration, over the dataset's
+}
+
+// DefaultConfig returns the catalog defaults
+func DefaultConfig() Config {
+	return Config{Interval: 24 * time.Hour}
+}
+
+// Dataset is the format of the bundled dataset and of files replacing it
+type Dataset struct {
+	Updated string  `json:"updated"` // YYYY-MM-DD the prices were checked
+	Prices  []Price `json:"prices"`
+}
+
+//go:embed prices.json
+var bundledDataset []byte
+
+// BundledDataset returns the prices built into the binary
+func BundledDataset() (*Dataset, error) {
+	return parseDataset(bundledDataset)
+}
+
+func parseDataset(data []byte) (*Dataset, error) {
+	var ds Dataset
+	if err := json.Unmarshal(data, &ds); err != nil {
+		return nil, fmt.Errorf("invalid pricing dataset: %w", err)
+	}
+	for i := range ds.Prices {
+		if err := ds.Prices[i].Validate(); err != nil {
+			return nil, fmt.Errorf("invalid pricing dataset: %w", err)
+		}
+	}
+	return &ds, nil
+}
+
+// RefreshReport counts what a refresh changed
+type RefreshReport struct {
+	Dataset string `json:"dataset"` // "bundled" or the dataset file's path
+	Updated string `json:"updated,omitempty"`
+	Added   int    `json:"added"`
+	Changed int    `json:"changed"`
+	Removed int    `json:"removed"`
+	Manual  int    `json:"manual"` // Prices set through the API, left alone
+}
+
+// Catalog answers price lookups from memory and writes changes through to
+// its store
+type Catalog struct {
+	store Store
+	cfg   Config
+
+	mu     sync.RWMutex
+	prices map[priceKey]*Price
+
+	refreshMu sync.Mutex // Serializes refreshes and edits
+	stopMu    sync.Mutex
+	stop      chan struct{}
+}
+
+// priceKey identifies a price: provider lowercased, model normalized by
+// modelKey
+type priceKey struct {
+	provider string
+	model    string
+}
+
+func keyOf(providerID, model string) priceKey {
+	return priceKey{provider: strings.ToLower(strings.TrimSpace(providerID)), model: modelKey(model)}
+}
+
+// modelKey drops the vendor prefix and case, so "openai/GPT-4o" and
+// "gpt-4o" share a price
+func modelKey(model string) string {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	return model
+}
+
+// NewCatalog loads the stored prices. A nil store keeps prices in memory.
+func NewCatalog(store Store, cfg Config) *Catalog {
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultConfig().Interval
+	}
+	c := &Catalog{store: store, cfg: cfg, prices: make(map[priceKey]*Price)}
+	if store != nil {
+		list, err := store.ListModelPrices()
+		if err != nil {
+			log.Printf("[Pricing] Failed to load prices: %v", err)
+		}
+		for _, p := range list {
+			c.prices[keyOf(p.ProviderID, p.Model)] = p
+		}
+	}
+	return c
+}
+
+// Lookup returns the price of a model on a provider. A price for the model
+// on that provider beats one for any provider, and dated or suffixed model
+// names fall back to their base name: gpt-4o-2024-08-06 is priced as
+// gpt-4o when it has no price of its own.
+func (c *Catalog) Lookup(providerID, model string) (*Price, bool) {
+	if c == nil || model == "" {
+		return nil, false
+	}
+	provider := strings.ToLower(strings.TrimSpace(providerID))
+	name := modelKey(model)
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for {
+		if p, ok := c.prices[priceKey{provider, name}]; ok && provider != "" {
+			cp := *p
+			return &cp, true
+		}
+		if p, ok := c.prices[priceKey{"", name}]; ok {
+			cp := *p
+			return &cp, true
+		}
+		i := strings.LastIndex(name, "-")
+		if i <= 0 {
+			return nil, false
+		}
+		name = name[:i]
+	}
+}
+
+// Cost prices a request on a provider's model, reporting false when the
+// model has no price
+func (c *Catalog) Cost(providerID, model string, promptTokens, completionTokens, cachedTokens int64) (float64, bool) {
+	p, ok := c.Lookup(providerID, model)
+	if !ok {
+		return 0, false
+	}
+	return p.Cost(promptTokens, completionTokens, cachedTokens), true
+}
+
+// List returns every price, by model then provider
+func (c *Catalog) List() []*Price {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	list := make([]*Price, 0, len(c.prices))
+	for _, p := range c.prices {
+		cp := *p
+		list = append(list, &cp)
+	}
+	c.mu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		if mi, mj := modelKey(list[i].Model), modelKey(list[j].Model); mi != mj {
+			return mi < mj
+		}
+		return list[i].ProviderID < list[j].ProviderID
+	})
+	return list
+}
+
+// Set saves a price edited through the API. Refreshes leave it alone until
+// it is deleted.
+func (c *Catalog) Set(p *Price) (*Price, error) {
+	if c == nil {
+		return nil, fmt.Errorf("pricing is not available")
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	cp := *p
+	cp.ProviderID = strings.TrimSpace(cp.ProviderID)
+	cp.Model = strings.TrimSpace(cp.Model)
+	cp.Source = SourceManual
+	cp.UpdatedAt = time.Now().UTC()
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	if err := c.save(&cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// Delete removes a price set through the API, reverting to the dataset's
+// or configuration's price if either has one. Other prices would only come
+// back on the next refresh, so they are overridden rather than deleted.
+func (c *Catalog) Delete(providerID, model string) error {
+	if c == nil {
+		return fmt.Errorf("pricing is not available")
+	}
+	key := keyOf(providerID, model)
+
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+	c.mu.RLock()
+	existing, ok := c.prices[key]
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("price for %s not found", model)
+	}
+	if existing.Source != SourceManual {
+		return fmt.Errorf("price for %s comes from the %s prices and cannot be deleted; set a price to override it", model, existing.Source)
+	}
+	if err := c.remove(existing); err != nil {
+		return err
+	}
+	want, _, err := c.desired()
+	if err != nil {
+		return err
+	}
+	if p, ok := want[key]; ok {
+		return c.save(p)
+	}
+	return nil
+}
+
+// Refresh reloads the dataset and the configured prices. Prices that
+// changed are saved, prices no longer listed are removed, and prices set
+// through the API are kept as they are.
+func (c *Catalog) Refresh(ctx context.Context) (*RefreshReport, error) {
+	if c == nil {
+		return nil, fmt.Errorf("pricing is not available")
+	}
+	c.refreshMu.Lock()
+	defer c.refreshMu.Unlock()
+
+	want, report, err := c.desired()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	current := make(map[priceKey]*Price, len(c.prices))
+	for k, p := range c.prices {
+		current[k] = p
+	}
+	c.mu.RUnlock()
+
+	for k, p := range current {
+		if p.Source == SourceManual {
+			report.Manual++
+			continue
+		}
+		if _, ok := want[k]; !ok {
+			if err := c.remove(p); err != nil {
+				return report, err
+			}
+			report.Removed++
+		}
+	}
+	for k, p := range want {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		old, ok := current[k]
+		switch {
+		case !ok:
+			report.Added++
+		case old.Source == SourceManual:
+			continue
+		case samePrice(old, p):
+			continue
+		default:
+			report.Changed++
+		}
+		if err := c.save(p); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+// desired returns the prices the dataset and configuration call for,
+// configuration first
+func (c *Catalog) desired() (map[priceKey]*Price, *RefreshReport, error) {
+	report := &RefreshReport{Dataset: SourceBundled}
+	data := bundledDataset
+	if c.cfg.DatasetPath != "" {
+		raw, err := os.ReadFile(c.cfg.DatasetPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read pricing dataset: %w", err)
+		}
+		data = raw
+		report.Dataset = c.cfg.DatasetPath
+	}
+	ds, err := parseDataset(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.Updated = ds.Updated
+
+	now := time.Now().UTC()
+	want := make(map[priceKey]*Price, len(ds.Prices)+len(c.cfg.Overrides))
+	add := func(p Price, source string) {
+		p.Source = source
+		p.UpdatedAt = now
+		want[keyOf(p.ProviderID, p.Model)] = &p
+	}
+	for _, p := range ds.Prices {
+		add(p, SourceBundled)
+	}
+	for _, p := range c.cfg.Overrides {
+		if err := p.Validate(); err != nil {
+			return nil, nil, err
+		}
+		add(p, SourceConfig)
+	}
+	return want, report, nil
+}
+
+func samePrice(a, b *Price) bool {
+	return a.Source == b.Source && a.Model == b.Model &&
+		a.InputPerMToken == b.InputPerMToken &&
+		a.OutputPerMToken == b.OutputPerMToken &&
+		a.CachedInputPerMToken == b.CachedInputPerMToken
+}
+
+func (c *Catalog) save(p *Price) error {
+	// Names that differ only in case or vendor prefix share a price
+	c.mu.RLock()
+	old, ok := c.prices[keyOf(p.ProviderID, p.Model)]
+	c.mu.RUnlock()
+	if ok && (old.ProviderID != p.ProviderID || old.Model != p.Model) {
+		if err := c.remove(old); err != nil {
+			return err
+		}
+	}
+	if c.store != nil {
+		if err := c.store.SaveModelPrice(p); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	c.prices[keyOf(p.ProviderID, p.Model)] = p
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *Catalog) remove(p *Price) error {
+	if c.store != nil {
+		if err := c.store.DeleteModelPrice(p.ProviderID, p.Model); err != nil {
+			return err
+		}
+	}
+	c.mu.Lock()
+	delete(c.prices, keyOf(p.ProviderID, p.Model))
+	c.mu.Unlock()
+	return nil
+}
+
+// Start refreshes the prices now and then every interval until ctx is
+// done or Close is called
+func (c *Catalog) Start(ctx context.Context) {
+	if c == nil {
+		return
+	}
+	c.stopMu.Lock()
+	if c.stop != nil {
+		c.stopMu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	c.stop = stop
+	c.stopMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(c.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			if report, err := c.Refresh(ctx); err != nil {
+				log.Printf("[Pricing] Refresh failed: %v", err)
+			} else if report.Added+report.Changed+report.Removed > 0 {
+				log.Printf("[Pricing] Refreshed from %s: %d added, %d changed, %d removed",
+					report.Dataset, report.Added, report.Changed, report.Removed)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops the periodic refresh
+func (c *Catalog) Close() {
+	if c == nil {
+		return
+	}
+	c.stopMu.Lock()
+	defer c.stopMu.Unlock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+}
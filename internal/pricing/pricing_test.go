@@ -0,0 +1,213 @@
+package pricing
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// memStore is a Store kept in a map
+type memStore struct {
+	prices map[priceKey]*Price
+}
+
+func newMemStore() *memStore {
+	return &memStore{prices: make(map[priceKey]*Price)}
+}
+
+func (m *memStore) SaveModelPrice(p *Price) error {
+	cp := *p
+	m.prices[priceKey{p.ProviderID, p.Model}] = &cp
+	return nil
+}
+
+func (m *memStore) ListModelPrices() ([]*Price, error) {
+	var list []*Price
+	for _, p := range m.prices {
+		cp := *p
+		list = append(list, &cp)
+	}
+	return list, nil
+}
+
+func (m *memStore) DeleteModelPrice(providerID, model string) error {
+	delete(m.prices, priceKey{providerID, model})
+	return nil
+}
+
+func writeDataset(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "prices.json")
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatalf("failed to write dataset: %v", err)
+	}
+	return path
+}
+
+func TestBundledDatasetIsValid(t *testing.T) {
+	ds, err := BundledDataset()
+	if err != nil {
+		t.Fatalf("bundled dataset failed to parse: %v", err)
+	}
+	if ds.Updated == "" || len(ds.Prices) == 0 {
+		t.Fatalf("expected a dated dataset with prices, got %q with %d", ds.Updated, len(ds.Prices))
+	}
+}
+
+func TestPriceCost(t *testing.T) {
+	p := &Price{Model: "m", InputPerMToken: 2, OutputPerMToken: 8, CachedInputPerMToken: 0.5}
+	// 600k uncached input at $2, 400k cached at $0.50, 100k output at $8
+	if got := p.Cost(1_000_000, 100_000, 400_000); math.Abs(got-2.2) > 1e-9 {
+		t.Errorf("expected $2.20, got %v", got)
+	}
+
+	p.CachedInputPerMToken = 0
+	if got := p.Cost(1_000_000, 0, 400_000); math.Abs(got-2) > 1e-9 {
+		t.Errorf("expected cached tokens at the input price without a cached rate, got %v", got)
+	}
+}
+
+func TestCatalogLookup(t *testing.T) {
+	path := writeDataset(t, `{"updated": "2026-01-01", "prices": [
+		{"model": "gpt-4o", "input_per_mtoken": 2.5, "output_per_mtoken": 10},
+		{"model": "gpt-4o-mini", "input_per_mtoken": 0.15, "output_per_mtoken": 0.6},
+		{"provider_id": "azure", "model": "gpt-4o", "input_per_mtoken": 3, "output_per_mtoken": 12}
+	]}`)
+	c := NewCatalog(nil, Config{DatasetPath: path})
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	cases := []struct {
+		provider, model string
+		input           float64
+	}{
+		{"", "gpt-4o", 2.5},
+		{"openai", "openai/GPT-4o", 2.5},
+		{"azure", "gpt-4o", 3},
+		{"", "gpt-4o-2024-08-06", 2.5},
+		{"", "gpt-4o-mini-2024-07-18", 0.15},
+	}
+	for _, tc := range cases {
+		p, ok := c.Lookup(tc.provider, tc.model)
+		if !ok {
+			t.Errorf("%s on %q: expected a price", tc.model, tc.provider)
+			continue
+		}
+		if p.InputPerMToken != tc.input {
+			t.Errorf("%s on %q: expected input at %v, got %v", tc.model, tc.provider, tc.input, p.InputPerMToken)
+		}
+	}
+
+	if _, ok := c.Lookup("", "llama-3"); ok {
+		t.Error("expected no price for an unlisted model")
+	}
+	if _, ok := c.Cost("", "llama-3", 100, 100, 0); ok {
+		t.Error("expected Cost to report an unlisted model")
+	}
+}
+
+func TestCatalogRefreshKeepsManualPrices(t *testing.T) {
+	path := writeDataset(t, `{"prices": [
+		{"model": "alpha", "input_per_mtoken": 1, "output_per_mtoken": 2},
+		{"model": "beta", "input_per_mtoken": 1, "output_per_mtoken": 2}
+	]}`)
+	store := newMemStore()
+	c := NewCatalog(store, Config{
+		DatasetPath: path,
+		Overrides:   []Price{{Model: "beta", InputPerMToken: 5, OutputPerMToken: 6}},
+	})
+	report, err := c.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if report.Added != 2 {
+		t.Errorf("expected 2 prices added, got %+v", report)
+	}
+	if p, _ := c.Lookup("", "beta"); p.InputPerMToken != 5 || p.Source != SourceConfig {
+		t.Errorf("expected the configured price over the dataset's, got %+v", p)
+	}
+
+	if _, err := c.Set(&Price{Model: "alpha", InputPerMToken: 9, OutputPerMToken: 9}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	// alpha goes up, and beta leaves the dataset but stays configured
+	if err := os.WriteFile(path, []byte(`{"prices": [
+		{"model": "alpha", "input_per_mtoken": 3, "output_per_mtoken": 4},
+		{"model": "gamma", "input_per_mtoken": 1, "output_per_mtoken": 1}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err = c.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if report.Manual != 1 || report.Added != 1 || report.Removed != 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	if p, _ := c.Lookup("", "alpha"); p.InputPerMToken != 9 {
+		t.Errorf("expected the manual price to survive a refresh, got %v", p.InputPerMToken)
+	}
+	if p, ok := c.Lookup("", "beta"); !ok || p.Source != SourceConfig {
+		t.Errorf("expected the configured price to outlive the dataset's, got %+v", p)
+	}
+
+	// A price set through the API reverts to the dataset's when deleted
+	if err := c.Delete("", "alpha"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if p, _ := c.Lookup("", "alpha"); p.InputPerMToken != 3 || p.Source != SourceBundled {
+		t.Errorf("expected the dataset's price after deleting, got %+v", p)
+	}
+	if err := c.Delete("", "alpha"); err == nil {
+		t.Error("expected deleting a dataset price to fail")
+	}
+	if err := c.Delete("", "missing"); err == nil {
+		t.Error("expected deleting an unknown price to fail")
+	}
+
+	// The store carries the prices over to a new catalog
+	reopened := NewCatalog(store, Config{DatasetPath: path})
+	if len(reopened.List()) != len(c.List()) {
+		t.Errorf("expected %d stored prices, got %d", len(c.List()), len(reopened.List()))
+	}
+}
+
+func TestCatalogRefreshRemovesStalePrices(t *testing.T) {
+	path := writeDataset(t, `{"prices": [
+		{"model": "alpha", "input_per_mtoken": 1, "output_per_mtoken": 2},
+		{"model": "beta", "input_per_mtoken": 1, "output_per_mtoken": 2}
+	]}`)
+	c := NewCatalog(newMemStore(), Config{DatasetPath: path})
+	if _, err := c.Refresh(context.Background()); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(`{"prices": [
+		{"model": "alpha", "input_per_mtoken": 1.5, "output_per_mtoken": 2}
+	]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	report, err := c.Refresh(context.Background())
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	if report.Changed != 1 || report.Removed != 1 {
+		t.Errorf("expected 1 changed and 1 removed, got %+v", report)
+	}
+	if _, ok := c.Lookup("", "beta"); ok {
+		t.Error("expected beta to be removed")
+	}
+}
+
+func TestCatalogSetValidates(t *testing.T) {
+	c := NewCatalog(nil, Config{})
+	if _, err := c.Set(&Price{Model: " "}); err == nil {
+		t.Error("expected a price without a model to be rejected")
+	}
+	if _, err := c.Set(&Price{Model: "m", InputPerMToken: -1}); err == nil {
+		t.Error("expected a negative price to be rejected")
+	}
+}
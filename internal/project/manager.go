@@ -6,6 +6,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/jordanhubbard/loom/internal/org"
 	"github.com/jordanhubbard/loom/pkg/models"
 )
 
@@ -91,6 +92,23 @@ func (m *Manager) ListProjects() []*models.Project {
 	return projects
 }
 
+// ListProjectsInOrg returns the projects of one organization; projects
+// without one belong to the default organization
+func (m *Manager) ListProjectsInOrg(orgID string) []*models.Project {
+	orgID = org.Normalize(orgID)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var projects []*models.Project
+	for _, project := range m.projects {
+		if org.Normalize(project.OrgID) == orgID {
+			projects = append(projects, project)
+		}
+	}
+
+	return projects
+}
+
 // UpdateProject updates a project
 func (m *Manager) UpdateProject(id string, updates map[string]interface{}) error {
 	m.mu.Lock()
@@ -129,6 +147,19 @@ func (m *Manager) UpdateProject(id string, updates map[string]interface{}) error
 	if gitStrategy, ok := updates["git_strategy"].(string); ok {
 		project.GitStrategy = models.GitStrategy(gitStrategy)
 	}
+	if orgID, ok := updates["org_id"].(string); ok {
+		project.OrgID = orgID
+	}
+	if optOut, ok := updates["shared_lessons_opt_out"].(bool); ok {
+		project.SharedLessonsOptOut = optOut
+	}
+	if compliance, ok := updates["compliance"].(*models.ComplianceConstraints); ok {
+		if compliance.Enabled() {
+			project.Compliance = compliance
+		} else {
+			project.Compliance = nil
+		}
+	}
 
 	project.UpdatedAt = time.Now()
 
@@ -0,0 +1,292 @@
+// Package projecttemplates keeps reusable project blueprints. A template
+// names the repository to clone, the personas that staff the project, the
+// providers their agents use, the beads to start with and the endpoints to
+// tell about the project's events. Instantiating a template is left to the
+// caller, which owns projects, agents, beads and webhooks.
+package projecttemplates
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// AnyRole assigns a provider to every agent whose role has no assignment of
+// its own
+const AnyRole = "*"
+
+// SeedBead is a bead filed in every project made from a template
+type SeedBead struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Priority    int    `json:"priority"`
+	Type        string `json:"type,omitempty"` // Defaults to task
+}
+
+// NotificationRule subscribes an endpoint to the events of every project
+// made from a template
+type NotificationRule struct {
+	Name       string   `json:"name,omitempty"`
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"` // e.g. bead.completed, workflow.*, or * for all
+}
+
+// Template is a project blueprint
+type Template struct {
+	ID            string             `json:"id"`
+	OrgID         string             `json:"org_id,omitempty"` // Empty shares the template with every organization
+	Name          string             `json:"name"`
+	Description   string             `json:"description,omitempty"`
+	GitRepo       string             `json:"git_repo,omitempty"` // May be left to the instantiation
+	Branch        string             `json:"branch"`
+	BeadsPath     string             `json:"beads_path"`
+	Context       map[string]string  `json:"context,omitempty"`
+	Personas      []string           `json:"personas,omitempty"`  // Staffed besides the default org chart
+	Providers     map[string]string  `json:"providers,omitempty"` // Role, or AnyRole, to provider ID
+	Beads         []SeedBead         `json:"beads,omitempty"`
+	Notifications []NotificationRule `json:"notifications,omitempty"`
+	CreatedBy     string             `json:"created_by,omitempty"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// Request creates or updates a template. On update, nil fields are left
+// unchanged.
+type Request struct {
+	Name          *string             `json:"name,omitempty"`
+	Description   *string             `json:"description,omitempty"`
+	GitRepo       *string             `json:"git_repo,omitempty"`
+	Branch        *string             `json:"branch,omitempty"`     // Defaults to main
+	BeadsPath     *string             `json:"beads_path,omitempty"` // Defaults to .beads
+	Context       *map[string]string  `json:"context,omitempty"`
+	Personas      *[]string           `json:"personas,omitempty"`
+	Providers     *map[string]string  `json:"providers,omitempty"`
+	Beads         *[]SeedBead         `json:"beads,omitempty"`
+	Notifications *[]NotificationRule `json:"notifications,omitempty"`
+}
+
+// InstantiateRequest makes a project from a template. Empty fields take the
+// template's values.
+type InstantiateRequest struct {
+	Name    string            `json:"name,omitempty"`
+	GitRepo string            `json:"git_repo,omitempty"`
+	Branch  string            `json:"branch,omitempty"`
+	OrgID   string            `json:"org_id,omitempty"`
+	Context map[string]string `json:"context,omitempty"` // Merged over the template's context
+	DryRun  bool              `json:"dry_run,omitempty"` // Only check the template and report issues
+}
+
+// Result reports what instantiating a template did, or for a dry run would
+// do
+type Result struct {
+	TemplateID           string          `json:"template_id"`
+	DryRun               bool            `json:"dry_run,omitempty"`
+	Name                 string          `json:"name"`
+	GitRepo              string          `json:"git_repo"`
+	Branch               string          `json:"branch"`
+	OrgID                string          `json:"org_id"`
+	Issues               []string        `json:"issues,omitempty"` // Prevent instantiation
+	Project              *models.Project `json:"project,omitempty"`
+	PublicKey            string          `json:"public_key,omitempty"` // SSH public key for deploy key setup
+	GitSetupInstructions string          `json:"git_setup_instructions,omitempty"`
+	Cloned               bool            `json:"cloned"`
+	AgentIDs             []string        `json:"agent_ids,omitempty"`
+	BeadIDs              []string        `json:"bead_ids,omitempty"`
+	Webhooks             []WebhookResult `json:"webhooks,omitempty"`
+	Warnings             []string        `json:"warnings,omitempty"` // Steps that failed without stopping the rest
+}
+
+// WebhookResult is a webhook subscribed for a notification rule. The secret
+// is shown only here.
+type WebhookResult struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+}
+
+// Store persists templates
+type Store interface {
+	// SaveProjectTemplate inserts or replaces a template
+	SaveProjectTemplate(t *Template) error
+	// GetProjectTemplate returns a template, or nil if unknown
+	GetProjectTemplate(id string) (*Template, error)
+	// ListProjectTemplates returns every template, oldest first
+	ListProjectTemplates() ([]*Template, error)
+	// DeleteProjectTemplate removes a template
+	DeleteProjectTemplate(id string) error
+}
+
+// Manager owns the templates
+type Manager struct {
+	store Store
+}
+
+// NewManager creates a manager backed by store
+func NewManager(store Store) *Manager {
+	if store == nil {
+		return nil
+	}
+	return &Manager{store: store}
+}
+
+// apply copies the set fields of req onto t
+func (req *Request) apply(t *Template) {
+	if req.Name != nil {
+		t.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Description != nil {
+		t.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.GitRepo != nil {
+		t.GitRepo = strings.TrimSpace(*req.GitRepo)
+	}
+	if req.Branch != nil {
+		t.Branch = strings.TrimSpace(*req.Branch)
+	}
+	if req.BeadsPath != nil {
+		t.BeadsPath = strings.TrimSpace(*req.BeadsPath)
+	}
+	if req.Context != nil {
+		t.Context = *req.Context
+	}
+	if req.Personas != nil {
+		t.Personas = *req.Personas
+	}
+	if req.Providers != nil {
+		t.Providers = *req.Providers
+	}
+	if req.Beads != nil {
+		t.Beads = *req.Beads
+	}
+	if req.Notifications != nil {
+		t.Notifications = *req.Notifications
+	}
+	if t.Branch == "" {
+		t.Branch = "main"
+	}
+	if t.BeadsPath == "" {
+		t.BeadsPath = ".beads"
+	}
+	for i := range t.Beads {
+		if t.Beads[i].Type == "" {
+			t.Beads[i].Type = "task"
+		}
+	}
+}
+
+// validate checks the fields a template needs to be instantiated
+func (t *Template) validate() error {
+	if t.Name == "" {
+		return fmt.Errorf("name must not be empty")
+	}
+	for _, p := range t.Personas {
+		if strings.TrimSpace(p) == "" {
+			return fmt.Errorf("personas must not contain empty names")
+		}
+	}
+	for role, providerID := range t.Providers {
+		if strings.TrimSpace(role) == "" || strings.TrimSpace(providerID) == "" {
+			return fmt.Errorf("providers must map roles to provider IDs")
+		}
+	}
+	for i, b := range t.Beads {
+		if strings.TrimSpace(b.Title) == "" {
+			return fmt.Errorf("bead %d needs a title", i+1)
+		}
+		if b.Priority < int(models.BeadPriorityP0) || b.Priority > int(models.BeadPriorityP3) {
+			return fmt.Errorf("bead %q priority must be between 0 and 3", b.Title)
+		}
+	}
+	for _, n := range t.Notifications {
+		u, err := url.Parse(n.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("notification url must be an absolute http or https URL")
+		}
+		if len(n.EventTypes) == 0 {
+			return fmt.Errorf("notification for %s must name at least one event type", n.URL)
+		}
+	}
+	return nil
+}
+
+// ProviderFor returns the provider a template assigns to agents of role,
+// or "" when it leaves them alone
+func (t *Template) ProviderFor(role string) string {
+	if id, ok := t.Providers[role]; ok {
+		return id
+	}
+	return t.Providers[AnyRole]
+}
+
+// Create adds a template owned by orgID
+func (m *Manager) Create(orgID string, req Request, createdBy string) (*Template, error) {
+	now := time.Now().UTC()
+	t := &Template{
+		ID:        uuid.New().String(),
+		OrgID:     orgID,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req.apply(t)
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	if err := m.store.SaveProjectTemplate(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Update changes the fields set in req
+func (m *Manager) Update(id string, req Request) (*Template, error) {
+	t, err := m.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	req.apply(t)
+	if err := t.validate(); err != nil {
+		return nil, err
+	}
+	t.UpdatedAt = time.Now().UTC()
+	if err := m.store.SaveProjectTemplate(t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Get returns a template
+func (m *Manager) Get(id string) (*Template, error) {
+	t, err := m.store.GetProjectTemplate(id)
+	if err != nil {
+		return nil, err
+	}
+	if t == nil {
+		return nil, fmt.Errorf("project template not found: %s", id)
+	}
+	return t, nil
+}
+
+// List returns every template, oldest first
+func (m *Manager) List() ([]*Template, error) {
+	list, err := m.store.ListProjectTemplates()
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		list = []*Template{}
+	}
+	return list, nil
+}
+
+// Delete removes a template. Projects made from it are left alone.
+func (m *Manager) Delete(id string) error {
+	if _, err := m.Get(id); err != nil {
+		return err
+	}
+	return m.store.DeleteProjectTemplate(id)
+}
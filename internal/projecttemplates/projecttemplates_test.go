@@ -0,0 +1,80 @@
+package projecttemplates_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/projecttemplates"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "projecttemplates.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestManager_CreateAppliesDefaultsAndValidates(t *testing.T) {
+	m := projecttemplates.NewManager(newTestDB(t))
+
+	tmpl, err := m.Create("acme", projecttemplates.Request{
+		Name:      strPtr(" Go service "),
+		Providers: &map[string]string{projecttemplates.AnyRole: "shared", "qa-engineer": "cheap"},
+		Beads:     &[]projecttemplates.SeedBead{{Title: "Set up CI"}},
+	}, "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if tmpl.Name != "Go service" || tmpl.Branch != "main" || tmpl.BeadsPath != ".beads" || tmpl.Beads[0].Type != "task" || tmpl.OrgID != "acme" {
+		t.Errorf("defaults not applied: %+v", tmpl)
+	}
+	if tmpl.ProviderFor("qa-engineer") != "cheap" || tmpl.ProviderFor("ceo") != "shared" {
+		t.Error("expected role assignments to win over the catch-all")
+	}
+
+	for name, req := range map[string]projecttemplates.Request{
+		"no name":          {},
+		"untitled bead":    {Name: strPtr("x"), Beads: &[]projecttemplates.SeedBead{{Priority: 1}}},
+		"bad priority":     {Name: strPtr("x"), Beads: &[]projecttemplates.SeedBead{{Title: "t", Priority: 7}}},
+		"bad webhook url":  {Name: strPtr("x"), Notifications: &[]projecttemplates.NotificationRule{{URL: "ftp://x", EventTypes: []string{"*"}}}},
+		"no event types":   {Name: strPtr("x"), Notifications: &[]projecttemplates.NotificationRule{{URL: "https://x"}}},
+		"empty persona":    {Name: strPtr("x"), Personas: &[]string{" "}},
+		"unnamed provider": {Name: strPtr("x"), Providers: &map[string]string{"ceo": ""}},
+	} {
+		if _, err := m.Create("", req, ""); err == nil {
+			t.Errorf("%s: expected Create to fail", name)
+		}
+	}
+}
+
+func TestManager_UpdateAndDelete(t *testing.T) {
+	m := projecttemplates.NewManager(newTestDB(t))
+	tmpl, err := m.Create("", projecttemplates.Request{Name: strPtr("svc"), GitRepo: strPtr("git@example.com:a/b.git")}, "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	updated, err := m.Update(tmpl.ID, projecttemplates.Request{Branch: strPtr("develop")})
+	if err != nil || updated.Branch != "develop" || updated.GitRepo != "git@example.com:a/b.git" {
+		t.Fatalf("Update = %+v, %v", updated, err)
+	}
+	if _, err := m.Update(tmpl.ID, projecttemplates.Request{Name: strPtr("")}); err == nil {
+		t.Error("expected an empty name to be rejected")
+	}
+
+	if err := m.Delete(tmpl.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := m.Get(tmpl.ID); err == nil {
+		t.Error("expected the template to be gone")
+	}
+	if err := m.Delete(tmpl.ID); err == nil {
+		t.Error("expected deleting an unknown template to fail")
+	}
+}
@@ -0,0 +1,241 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAzureAPIVersion is the Azure OpenAI API version used when the
+// endpoint does not set one
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// azureScope is the scope of AAD tokens for Azure OpenAI
+const azureScope = "https://cognitiveservices.azure.com/.default"
+
+// azureTokenSkew is how long before expiry an AAD token is renewed
+const azureTokenSkew = 5 * time.Minute
+
+// AzureOpenAIProvider implements Protocol for Azure OpenAI. Requests go to
+// a deployment rather than a model, with the API version as a query
+// parameter, and authenticate with either the resource's API key or an
+// Azure AD token.
+//
+// The endpoint is the resource URL; its query may set api-version and, for
+// AAD authentication, tenant_id and client_id, in which case the API key is
+// the client secret:
+//
+//	https://myresource.openai.azure.com?api-version=2024-10-21&tenant_id=...&client_id=...
+type AzureOpenAIProvider struct {
+	*OpenAIProvider
+	deployment string // Used when a request names no model
+	apiVersion string
+	tenantID   string
+	clientID   string
+	tokenURL   string // AAD token endpoint of the tenant
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewAzureOpenAIProvider creates an Azure OpenAI provider for the resource
+// at endpoint. deployment is the deployment requests without a model go to.
+func NewAzureOpenAIProvider(endpoint, apiKey, deployment string) (*AzureOpenAIProvider, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid azure endpoint %q", endpoint)
+	}
+	q := u.Query()
+	p := &AzureOpenAIProvider{
+		deployment: deployment,
+		apiVersion: q.Get("api-version"),
+		tenantID:   q.Get("tenant_id"),
+		clientID:   q.Get("client_id"),
+	}
+	if p.apiVersion == "" {
+		p.apiVersion = DefaultAzureAPIVersion
+	}
+	if (p.tenantID == "") != (p.clientID == "") {
+		return nil, fmt.Errorf("azure endpoint needs both tenant_id and client_id for AAD authentication")
+	}
+	if p.tenantID != "" {
+		p.tokenURL = fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(p.tenantID))
+	}
+	// An endpoint copied from the portal may include /openai; requests add it
+	base := strings.TrimSuffix(strings.TrimSuffix(u.Path, "/"), "/openai")
+	p.OpenAIProvider = NewOpenAIProvider(u.Scheme+"://"+u.Host+base, apiKey)
+	return p, nil
+}
+
+// deploymentURL returns the URL of an operation on a deployment
+func (p *AzureOpenAIProvider) deploymentURL(deployment, operation string) string {
+	if deployment == "" {
+		deployment = p.deployment
+	}
+	return fmt.Sprintf("%s/openai/deployments/%s/%s?api-version=%s",
+		p.endpoint, url.PathEscape(deployment), operation, url.QueryEscape(p.apiVersion))
+}
+
+// authorize sets the request's credentials: an AAD bearer token when the
+// endpoint names a tenant, otherwise the API key
+func (p *AzureOpenAIProvider) authorize(ctx context.Context, req *http.Request) error {
+	if p.tenantID == "" {
+		if p.apiKey != "" {
+			req.Header.Set("api-key", p.apiKey)
+		}
+		return nil
+	}
+	token, err := p.aadToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// aadToken returns a cached AAD token, fetching a new one with the client
+// credentials when it is about to expire
+func (p *AzureOpenAIProvider) aadToken(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token != "" && time.Now().Before(p.tokenExpiry.Add(-azureTokenSkew)) {
+		return p.token, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {p.clientID},
+		"client_secret": {p.apiKey},
+		"scope":         {azureScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get azure AD token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("azure AD token request failed with status %d: %s", resp.StatusCode, string(body))
+	}
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil || tok.AccessToken == "" {
+		return "", fmt.Errorf("invalid azure AD token response: %s", string(body))
+	}
+	p.token = tok.AccessToken
+	p.tokenExpiry = time.Now().Add(time.Duration(tok.ExpiresIn) * time.Second)
+	return p.token, nil
+}
+
+// CreateChatCompletion sends a chat completion request to the deployment
+// named by the request's model
+func (p *AzureOpenAIProvider) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.deploymentURL(req.Model, "chat/completions"), strings.NewReader(string(body)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if err := p.authorize(ctx, httpReq); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, chatCompletionError(req, resp.StatusCode, string(respBody))
+	}
+
+	var completionResp ChatCompletionResponse
+	if err := unmarshalJSON(respBody, &completionResp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &completionResp, nil
+}
+
+// CreateChatCompletionStream sends a streaming chat completion request to
+// the deployment named by the request's model
+func (p *AzureOpenAIProvider) CreateChatCompletionStream(ctx context.Context, req *ChatCompletionRequest, handler StreamHandler) error {
+	req.Stream = true
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.deploymentURL(req.Model, "chat/completions"), strings.NewReader(string(body)))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	if err := p.authorize(ctx, httpReq); err != nil {
+		return err
+	}
+
+	resp, err := p.streamingClient.Do(httpReq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("request cancelled: %w", ctx.Err())
+		}
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return chatCompletionError(req, resp.StatusCode, string(respBody))
+	}
+	return p.readStreamingResponse(ctx, resp.Body, handler)
+}
+
+// GetModels checks that the resource answers and returns the configured
+// deployment. Azure lists the resource's base models rather than its
+// deployments, so they cannot be negotiated between.
+func (p *AzureOpenAIProvider) GetModels(ctx context.Context) ([]Model, error) {
+	u := fmt.Sprintf("%s/openai/models?api-version=%s", p.endpoint, url.QueryEscape(p.apiVersion))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if err := p.authorize(ctx, httpReq); err != nil {
+		return nil, err
+	}
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+	if p.deployment == "" {
+		return nil, fmt.Errorf("azure provider has no deployment configured")
+	}
+	return []Model{{ID: p.deployment, Object: "model", OwnedBy: "azure"}}, nil
+}
@@ -0,0 +1,99 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAzureOpenAIProvider_APIKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/openai/deployments/gpt4o-prod/chat/completions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("api-version"); got != "2024-06-01" {
+			t.Errorf("expected api-version 2024-06-01, got %q", got)
+		}
+		if got := r.Header.Get("api-key"); got != "secret" {
+			t.Errorf("expected api-key header, got %q", got)
+		}
+		if r.Header.Get("Authorization") != "" {
+			t.Error("expected no bearer token with an API key")
+		}
+		fmt.Fprint(w, `{"id":"1","model":"gpt-4o","choices":[{"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}]}`)
+	}))
+	defer server.Close()
+
+	p, err := NewAzureOpenAIProvider(server.URL+"/openai/?api-version=2024-06-01", "secret", "gpt4o-prod")
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIProvider: %v", err)
+	}
+	resp, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hello"}}})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "hi" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestAzureOpenAIProvider_AAD(t *testing.T) {
+	tokenRequests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			tokenRequests++
+			_ = r.ParseForm()
+			if r.Form.Get("client_id") != "app" || r.Form.Get("client_secret") != "shh" || r.Form.Get("scope") != azureScope {
+				t.Errorf("unexpected token request: %v", r.Form)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "aad-token", "expires_in": 3600})
+		case strings.HasPrefix(r.URL.Path, "/openai/models"):
+			if got := r.Header.Get("Authorization"); got != "Bearer aad-token" {
+				t.Errorf("expected AAD bearer token, got %q", got)
+			}
+			if r.Header.Get("api-key") != "" {
+				t.Error("expected no api-key header with AAD")
+			}
+			fmt.Fprint(w, `{"data":[{"id":"gpt-4o"}]}`)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewAzureOpenAIProvider(server.URL+"?tenant_id=contoso&client_id=app", "shh", "gpt4o-prod")
+	if err != nil {
+		t.Fatalf("NewAzureOpenAIProvider: %v", err)
+	}
+	if p.apiVersion != DefaultAzureAPIVersion {
+		t.Errorf("expected default api version, got %q", p.apiVersion)
+	}
+	p.tokenURL = server.URL + "/token"
+
+	for i := 0; i < 2; i++ {
+		models, err := p.GetModels(context.Background())
+		if err != nil {
+			t.Fatalf("GetModels: %v", err)
+		}
+		if len(models) != 1 || models[0].ID != "gpt4o-prod" {
+			t.Errorf("expected the configured deployment, got %+v", models)
+		}
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected the token to be cached, got %d token requests", tokenRequests)
+	}
+}
+
+func TestNewAzureOpenAIProvider_Invalid(t *testing.T) {
+	if _, err := NewAzureOpenAIProvider("not a url", "", "d"); err == nil {
+		t.Error("expected an error for an endpoint without a host")
+	}
+	if _, err := NewAzureOpenAIProvider("https://res.openai.azure.com?tenant_id=t", "", "d"); err == nil {
+		t.Error("expected an error for a tenant without a client")
+	}
+}
@@ -0,0 +1,281 @@
+package provider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Bedrock model families, which each take their own request body
+const (
+	BedrockFamilyClaude = "claude"
+	BedrockFamilyLlama  = "llama"
+)
+
+const (
+	bedrockService          = "bedrock" // Signing name of the runtime and control plane
+	bedrockAnthropicVersion = "bedrock-2023-05-31"
+	defaultLlamaMaxGenLen   = 2048
+)
+
+// BedrockProvider implements Protocol for AWS Bedrock. Requests are signed
+// with SigV4 and sent to InvokeModel in the body of the model's family:
+// the Anthropic Messages API for Claude and a formatted prompt for Llama.
+//
+// The endpoint is the bedrock-runtime URL of the region, such as
+// https://bedrock-runtime.us-east-1.amazonaws.com; a region query parameter
+// names the region of endpoints it cannot be read from, such as VPC
+// endpoints. The API key is ACCESS_KEY_ID:SECRET_ACCESS_KEY[:SESSION_TOKEN],
+// or empty to use the AWS_* environment variables.
+type BedrockProvider struct {
+	endpoint        string // bedrock-runtime, for requests
+	controlEndpoint string // bedrock, for listing models
+	region          string
+	model           string // Used when a request names no model
+	creds           awsCredentials
+	credsErr        error // Reported by every request when the credentials are unusable
+	client          *http.Client
+}
+
+// NewBedrockProvider creates a Bedrock provider for the region of endpoint.
+// model is the model ID requests without a model go to.
+func NewBedrockProvider(endpoint, apiKey, model string) (*BedrockProvider, error) {
+	u, err := url.Parse(strings.TrimSpace(endpoint))
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid bedrock endpoint %q", endpoint)
+	}
+	region := u.Query().Get("region")
+	if region == "" {
+		region = bedrockRegion(u.Hostname())
+	}
+	if region == "" {
+		return nil, fmt.Errorf("cannot tell the AWS region of bedrock endpoint %q; add ?region=", endpoint)
+	}
+	p := &BedrockProvider{
+		endpoint:        u.Scheme + "://" + u.Host + strings.TrimSuffix(u.Path, "/"),
+		controlEndpoint: fmt.Sprintf("https://bedrock.%s.amazonaws.com", region),
+		region:          region,
+		model:           model,
+		client:          &http.Client{Timeout: 15 * time.Minute},
+	}
+	p.creds, p.credsErr = parseAWSCredentials(apiKey)
+	return p, nil
+}
+
+// bedrockRegion reads the region from a bedrock-runtime host name
+func bedrockRegion(host string) string {
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if strings.HasPrefix(label, "bedrock-runtime") && i+1 < len(labels) {
+			return labels[i+1]
+		}
+	}
+	return ""
+}
+
+// BedrockFamily returns the family of a Bedrock model ID, or "" for
+// families Loom cannot talk to. Cross-region inference profile IDs, such as
+// us.anthropic.claude-..., belong to the family of their model.
+func BedrockFamily(modelID string) string {
+	switch {
+	case strings.Contains(modelID, "anthropic.claude"):
+		return BedrockFamilyClaude
+	case strings.Contains(modelID, "meta.llama"):
+		return BedrockFamilyLlama
+	default:
+		return ""
+	}
+}
+
+// CreateChatCompletion invokes the request's model
+func (p *BedrockProvider) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if p.credsErr != nil {
+		return nil, p.credsErr
+	}
+	model := req.Model
+	if model == "" {
+		model = p.model
+	}
+
+	var body []byte
+	var err error
+	family := BedrockFamily(model)
+	switch family {
+	case BedrockFamilyClaude:
+		body, err = bedrockClaudeBody(req)
+	case BedrockFamilyLlama:
+		body, err = json.Marshal(bedrockLlamaBody(req))
+	default:
+		return nil, fmt.Errorf("unsupported bedrock model %q: only Claude and Llama models are supported", model)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	u := fmt.Sprintf("%s/model/%s/invoke", p.endpoint, sigV4Escape(model))
+	respBody, status, err := p.do(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, chatCompletionError(req, status, string(respBody))
+	}
+
+	var resp *ChatCompletionResponse
+	switch family {
+	case BedrockFamilyClaude:
+		var out anthropicResponse
+		if err := json.Unmarshal(respBody, &out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		resp = out.toCanonical()
+	case BedrockFamilyLlama:
+		var out bedrockLlamaResponse
+		if err := json.Unmarshal(respBody, &out); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		resp = out.toCanonical()
+	}
+	if resp.Model == "" {
+		resp.Model = model
+	}
+	return resp, nil
+}
+
+// GetModels lists the Claude and Llama models of the region. With a model
+// configured, only that model is returned, once the region is confirmed to
+// offer it.
+func (p *BedrockProvider) GetModels(ctx context.Context) ([]Model, error) {
+	if p.credsErr != nil {
+		return nil, p.credsErr
+	}
+	body, status, err := p.do(ctx, http.MethodGet, p.controlEndpoint+"/foundation-models?byOutputModality=TEXT", nil)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", status, string(body))
+	}
+	var list struct {
+		ModelSummaries []struct {
+			ModelID      string `json:"modelId"`
+			ProviderName string `json:"providerName"`
+		} `json:"modelSummaries"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var models []Model
+	for _, m := range list.ModelSummaries {
+		if BedrockFamily(m.ModelID) == "" {
+			continue
+		}
+		if p.model != "" && !strings.HasSuffix(p.model, m.ModelID) {
+			continue
+		}
+		id := m.ModelID
+		if p.model != "" {
+			id = p.model // Keep the inference profile prefix
+		}
+		models = append(models, Model{ID: id, Object: "model", OwnedBy: strings.ToLower(m.ProviderName)})
+	}
+	if p.model != "" && len(models) == 0 {
+		return nil, fmt.Errorf("bedrock model %s is not available in %s", p.model, p.region)
+	}
+	return models, nil
+}
+
+// do sends a signed request and returns the response body and status
+func (p *BedrockProvider) do(ctx context.Context, method, u string, body []byte) ([]byte, int, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	signV4(httpReq, body, p.creds, p.region, bedrockService, time.Now())
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read response: %w", err)
+	}
+	return respBody, resp.StatusCode, nil
+}
+
+// bedrockClaudeBody encodes a request for Claude: the Messages API without
+// model and stream, which Bedrock rejects, and with its API version
+func bedrockClaudeBody(req *ChatCompletionRequest) ([]byte, error) {
+	raw, err := json.Marshal(anthropicRequestFromCanonical(req))
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "model")
+	delete(fields, "stream")
+	fields["anthropic_version"], _ = json.Marshal(bedrockAnthropicVersion)
+	return json.Marshal(fields)
+}
+
+type bedrockLlamaRequest struct {
+	Prompt      string  `json:"prompt"`
+	MaxGenLen   int     `json:"max_gen_len"`
+	Temperature float64 `json:"temperature,omitempty"`
+}
+
+type bedrockLlamaResponse struct {
+	Generation           string `json:"generation"`
+	PromptTokenCount     int    `json:"prompt_token_count"`
+	GenerationTokenCount int    `json:"generation_token_count"`
+	StopReason           string `json:"stop_reason"`
+}
+
+// bedrockLlamaBody formats the conversation in the Llama 3 chat template.
+// Tools are not declared: Llama on Bedrock has no native tool calling.
+func bedrockLlamaBody(req *ChatCompletionRequest) *bedrockLlamaRequest {
+	var prompt strings.Builder
+	prompt.WriteString("<|begin_of_text|>")
+	for _, m := range req.Messages {
+		role := m.Role
+		if role == "tool" {
+			role = "ipython"
+		}
+		fmt.Fprintf(&prompt, "<|start_header_id|>%s<|end_header_id|>\n\n%s<|eot_id|>", role, m.Content)
+	}
+	prompt.WriteString("<|start_header_id|>assistant<|end_header_id|>\n\n")
+
+	out := &bedrockLlamaRequest{Prompt: prompt.String(), MaxGenLen: req.MaxTokens, Temperature: req.Temperature}
+	if out.MaxGenLen <= 0 {
+		out.MaxGenLen = defaultLlamaMaxGenLen
+	}
+	return out
+}
+
+func (r *bedrockLlamaResponse) toCanonical() *ChatCompletionResponse {
+	finish := FinishStop
+	if r.StopReason == "length" {
+		finish = FinishLength
+	}
+	resp := &ChatCompletionResponse{Object: "chat.completion"}
+	resp.Choices = append(resp.Choices, newChoice(ChatMessage{Role: "assistant", Content: strings.TrimSpace(r.Generation)}, finish))
+	resp.Usage.PromptTokens = r.PromptTokenCount
+	resp.Usage.CompletionTokens = r.GenerationTokenCount
+	resp.Usage.TotalTokens = r.PromptTokenCount + r.GenerationTokenCount
+	return resp
+}
@@ -0,0 +1,169 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignV4(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	creds := awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"}
+	signV4(req, nil, creds, "us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != want {
+		t.Errorf("unexpected signature:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestParseAWSCredentials(t *testing.T) {
+	creds, err := parseAWSCredentials("AKID:secret:token")
+	if err != nil || creds.AccessKeyID != "AKID" || creds.SecretAccessKey != "secret" || creds.SessionToken != "token" {
+		t.Errorf("unexpected credentials %+v, %v", creds, err)
+	}
+	if _, err := parseAWSCredentials("AKID"); err == nil {
+		t.Error("expected an error without a secret")
+	}
+
+	t.Setenv("AWS_ACCESS_KEY_ID", "ENVKEY")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "envsecret")
+	if creds, err := parseAWSCredentials(""); err != nil || creds.AccessKeyID != "ENVKEY" {
+		t.Errorf("expected environment credentials, got %+v, %v", creds, err)
+	}
+}
+
+func TestBedrockRegion(t *testing.T) {
+	if got := bedrockRegion("bedrock-runtime.eu-west-1.amazonaws.com"); got != "eu-west-1" {
+		t.Errorf("expected eu-west-1, got %q", got)
+	}
+	if _, err := NewBedrockProvider("https://vpce-123.example.com", "a:b", ""); err == nil {
+		t.Error("expected an error without a region")
+	}
+	p, err := NewBedrockProvider("https://vpce-123.example.com?region=us-west-2", "a:b", "")
+	if err != nil || p.region != "us-west-2" {
+		t.Errorf("expected region from the query, got %v", err)
+	}
+}
+
+// newTestBedrock returns a provider sending runtime and control plane
+// requests to server
+func newTestBedrock(t *testing.T, server *httptest.Server, model string) *BedrockProvider {
+	t.Helper()
+	p, err := NewBedrockProvider(server.URL+"?region=us-east-1", "AKID:secret", model)
+	if err != nil {
+		t.Fatalf("NewBedrockProvider: %v", err)
+	}
+	p.controlEndpoint = server.URL
+	return p
+}
+
+func TestBedrockProvider_Claude(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/model/us.anthropic.claude-3-5-sonnet-20240620-v1%3A0/invoke" {
+			t.Errorf("unexpected path %s", r.URL.EscapedPath())
+		}
+		if auth := r.Header.Get("Authorization"); !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "/us-east-1/bedrock/aws4_request") {
+			t.Errorf("unexpected authorization %q", auth)
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["anthropic_version"] != bedrockAnthropicVersion || body["system"] != "be brief" {
+			t.Errorf("unexpected body %v", body)
+		}
+		if _, ok := body["model"]; ok {
+			t.Error("expected no model in the body")
+		}
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`)
+	}))
+	defer server.Close()
+
+	p := newTestBedrock(t, server, "us.anthropic.claude-3-5-sonnet-20240620-v1:0")
+	resp, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+		Messages: []ChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "hello"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "ok" || resp.Usage.TotalTokens != 12 || resp.Model != p.model {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestBedrockProvider_Llama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body bedrockLlamaRequest
+		_ = json.Unmarshal(raw, &body)
+		if !strings.Contains(body.Prompt, "<|start_header_id|>user<|end_header_id|>\n\nhello<|eot_id|>") ||
+			!strings.HasSuffix(body.Prompt, "<|start_header_id|>assistant<|end_header_id|>\n\n") {
+			t.Errorf("unexpected prompt %q", body.Prompt)
+		}
+		if body.MaxGenLen != defaultLlamaMaxGenLen {
+			t.Errorf("expected default max_gen_len, got %d", body.MaxGenLen)
+		}
+		fmt.Fprint(w, `{"generation":" hi there","prompt_token_count":8,"generation_token_count":3,"stop_reason":"length"}`)
+	}))
+	defer server.Close()
+
+	p := newTestBedrock(t, server, "meta.llama3-1-70b-instruct-v1:0")
+	resp, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hello"}}})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "hi there" || resp.Choices[0].Finish != FinishLength || resp.Usage.CompletionTokens != 3 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+
+	if _, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Model: "amazon.titan-text-express-v1"}); err == nil {
+		t.Error("expected an error for an unsupported model family")
+	}
+}
+
+func TestBedrockProvider_ContextLength(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"message":"Input is too long for requested model."}`)
+	}))
+	defer server.Close()
+
+	p := newTestBedrock(t, server, "anthropic.claude-3-haiku-20240307-v1:0")
+	_, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "x"}}})
+	if _, ok := err.(*ContextLengthError); !ok {
+		t.Errorf("expected a ContextLengthError, got %v", err)
+	}
+}
+
+func TestBedrockProvider_GetModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/foundation-models" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		fmt.Fprint(w, `{"modelSummaries":[
+			{"modelId":"anthropic.claude-3-haiku-20240307-v1:0","providerName":"Anthropic"},
+			{"modelId":"meta.llama3-1-70b-instruct-v1:0","providerName":"Meta"},
+			{"modelId":"amazon.titan-text-express-v1","providerName":"Amazon"}]}`)
+	}))
+	defer server.Close()
+
+	models, err := newTestBedrock(t, server, "").GetModels(context.Background())
+	if err != nil || len(models) != 2 {
+		t.Fatalf("expected the Claude and Llama models, got %+v, %v", models, err)
+	}
+
+	models, err = newTestBedrock(t, server, "us.anthropic.claude-3-haiku-20240307-v1:0").GetModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "us.anthropic.claude-3-haiku-20240307-v1:0" {
+		t.Fatalf("expected the configured inference profile, got %+v, %v", models, err)
+	}
+
+	if _, err := newTestBedrock(t, server, "anthropic.claude-unknown").GetModels(context.Background()); err == nil {
+		t.Error("expected an error for a model the region does not offer")
+	}
+}
@@ -0,0 +1,76 @@
+package provider
+
+import (
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/plugin"
+)
+
+// CapabilityReporter is implemented by protocols that describe what their
+// models support, in the Capabilities model plugins declare in their
+// metadata
+type CapabilityReporter interface {
+	Capabilities(model string) plugin.Capabilities
+}
+
+// tierCapabilityPrefix prefixes the custom capability declaring a model's
+// tier, for models whose names carry no parameter count
+const tierCapabilityPrefix = "tier_"
+
+// TierCapability returns the custom capability declaring a model tier,
+// such as tier_large
+func TierCapability(tier ModelTier) string {
+	return tierCapabilityPrefix + tier.String()
+}
+
+// CapabilityTier returns the model tier declared by capabilities
+func CapabilityTier(caps plugin.Capabilities) (ModelTier, bool) {
+	for name, declared := range caps.CustomCapabilities {
+		if !declared || !strings.HasPrefix(name, tierCapabilityPrefix) {
+			continue
+		}
+		for _, tier := range []ModelTier{TierSmall, TierMedium, TierLarge, TierXLarge} {
+			if name == TierCapability(tier) {
+				return tier, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// tierParamsB is a parameter count within each tier, standing in for the
+// size of models that declare a tier instead
+var tierParamsB = map[ModelTier]float64{
+	TierSmall:  8,
+	TierMedium: 30,
+	TierLarge:  120,
+	TierXLarge: 400,
+}
+
+// Capabilities returns what a provider's model supports, for providers
+// whose protocol reports it
+func (r *Registry) Capabilities(providerID string) (plugin.Capabilities, bool) {
+	registered, err := r.Get(providerID)
+	if err != nil || registered.Config == nil {
+		return plugin.Capabilities{}, false
+	}
+	reporter, ok := registered.Protocol.(CapabilityReporter)
+	if !ok {
+		return plugin.Capabilities{}, false
+	}
+	return reporter.Capabilities(registered.Config.Model), true
+}
+
+// declaredParamsB returns the parameter count standing in for a provider
+// whose model declares its tier, or 0
+func (r *Registry) declaredParamsB(providerID string) float64 {
+	caps, ok := r.Capabilities(providerID)
+	if !ok {
+		return 0
+	}
+	tier, ok := CapabilityTier(caps)
+	if !ok {
+		return 0
+	}
+	return tierParamsB[tier]
+}
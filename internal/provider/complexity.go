@@ -40,6 +40,21 @@ const (
 	TierXLarge ModelTier = 4 // 200B+ params
 )
 
+func (t ModelTier) String() string {
+	switch t {
+	case TierSmall:
+		return "small"
+	case TierMedium:
+		return "medium"
+	case TierLarge:
+		return "large"
+	case TierXLarge:
+		return "xlarge"
+	default:
+		return "unknown"
+	}
+}
+
 // GetModelTier returns the tier for a given model size.
 func GetModelTier(paramsB float64) ModelTier {
 	switch {
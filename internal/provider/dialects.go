@@ -0,0 +1,781 @@
+package provider
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Provider API dialects. ChatCompletionRequest and ChatCompletionResponse
+// (the OpenAI shape) are the canonical internal format; the converters below
+// translate the other dialects to and from it so caching, analytics and
+// middleware only ever see one shape.
+const (
+	DialectOpenAI    = "openai"
+	DialectAnthropic = "anthropic"
+	DialectGemini    = "gemini"
+)
+
+// Canonical finish reasons
+const (
+	FinishStop          = "stop"
+	FinishLength        = "length"
+	FinishToolCalls     = "tool_calls"
+	FinishContentFilter = "content_filter"
+)
+
+const defaultAnthropicMaxTokens = 4096
+
+// ToCanonicalRequest parses a request body in the given dialect
+func ToCanonicalRequest(dialect string, body []byte) (*ChatCompletionRequest, error) {
+	switch dialect {
+	case DialectOpenAI, "":
+		var req ChatCompletionRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("invalid openai request: %w", err)
+		}
+		return &req, nil
+	case DialectAnthropic:
+		var req anthropicRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("invalid anthropic request: %w", err)
+		}
+		return req.toCanonical()
+	case DialectGemini:
+		var req geminiRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return nil, fmt.Errorf("invalid gemini request: %w", err)
+		}
+		return req.toCanonical(), nil
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", dialect)
+	}
+}
+
+// FromCanonicalRequest encodes a canonical request in the given dialect
+func FromCanonicalRequest(dialect string, req *ChatCompletionRequest) ([]byte, error) {
+	switch dialect {
+	case DialectOpenAI, "":
+		return json.Marshal(req)
+	case DialectAnthropic:
+		return json.Marshal(anthropicRequestFromCanonical(req))
+	case DialectGemini:
+		return json.Marshal(geminiRequestFromCanonical(req))
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", dialect)
+	}
+}
+
+// ToCanonicalResponse parses a response body in the given dialect
+func ToCanonicalResponse(dialect string, body []byte) (*ChatCompletionResponse, error) {
+	switch dialect {
+	case DialectOpenAI, "":
+		var resp ChatCompletionResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("invalid openai response: %w", err)
+		}
+		return &resp, nil
+	case DialectAnthropic:
+		var resp anthropicResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("invalid anthropic response: %w", err)
+		}
+		return resp.toCanonical(), nil
+	case DialectGemini:
+		var resp geminiResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("invalid gemini response: %w", err)
+		}
+		return resp.toCanonical(), nil
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", dialect)
+	}
+}
+
+// FromCanonicalResponse encodes a canonical response in the given dialect
+func FromCanonicalResponse(dialect string, resp *ChatCompletionResponse) ([]byte, error) {
+	switch dialect {
+	case DialectOpenAI, "":
+		return json.Marshal(resp)
+	case DialectAnthropic:
+		return json.Marshal(anthropicResponseFromCanonical(resp))
+	case DialectGemini:
+		return json.Marshal(geminiResponseFromCanonical(resp))
+	default:
+		return nil, fmt.Errorf("unsupported dialect: %s", dialect)
+	}
+}
+
+// ConvertRequest translates a request body between dialects
+func ConvertRequest(from, to string, body []byte) ([]byte, error) {
+	req, err := ToCanonicalRequest(from, body)
+	if err != nil {
+		return nil, err
+	}
+	return FromCanonicalRequest(to, req)
+}
+
+// ConvertResponse translates a response body between dialects
+func ConvertResponse(from, to string, body []byte) ([]byte, error) {
+	resp, err := ToCanonicalResponse(from, body)
+	if err != nil {
+		return nil, err
+	}
+	return FromCanonicalResponse(to, resp)
+}
+
+// DetectRequestDialect guesses the dialect of a request body from its
+// top-level fields. Unknown shapes are treated as OpenAI.
+func DetectRequestDialect(body []byte) string {
+	var probe map[string]json.RawMessage
+	if err := json.Unmarshal(body, &probe); err != nil {
+		return DialectOpenAI
+	}
+	if _, ok := probe["contents"]; ok {
+		return DialectGemini
+	}
+	if _, ok := probe["system"]; ok {
+		return DialectAnthropic
+	}
+	if raw, ok := probe["messages"]; ok {
+		// Anthropic content is always a string or a list of typed blocks;
+		// a list in an OpenAI message only appears for multimodal input.
+		var msgs []struct {
+			Content json.RawMessage `json:"content"`
+		}
+		if json.Unmarshal(raw, &msgs) == nil {
+			for _, m := range msgs {
+				var blocks []struct {
+					Type string `json:"type"`
+				}
+				if json.Unmarshal(m.Content, &blocks) == nil {
+					for _, b := range blocks {
+						if b.Type == "tool_use" || b.Type == "tool_result" {
+							return DialectAnthropic
+						}
+					}
+				}
+			}
+		}
+	}
+	return DialectOpenAI
+}
+
+// CanonicalizeRequestBody rewrites a logged request body of another dialect
+// as a canonical (OpenAI-shaped) request. OpenAI and unparseable bodies are
+// returned unchanged.
+func CanonicalizeRequestBody(body string) string {
+	dialect := DetectRequestDialect([]byte(body))
+	if body == "" || dialect == DialectOpenAI {
+		return body
+	}
+	req, err := ToCanonicalRequest(dialect, []byte(body))
+	if err != nil {
+		return body
+	}
+	out, err := json.Marshal(req)
+	if err != nil {
+		return body
+	}
+	return string(out)
+}
+
+// --- Anthropic Messages API ---
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        json.RawMessage    `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int                `json:"max_tokens"`
+	Temperature   float64            `json:"temperature,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content anthropicContent `json:"content"`
+}
+
+// anthropicContent accepts either a plain string or a list of blocks
+type anthropicContent []anthropicBlock
+
+func (c *anthropicContent) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = anthropicContent{{Type: "text", Text: text}}
+		return nil
+	}
+	var blocks []anthropicBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	*c = blocks
+	return nil
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"` // text, tool_use, tool_result
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Input     json.RawMessage `json:"input,omitempty"`
+	ToolUseID string          `json:"tool_use_id,omitempty"`
+	Content   string          `json:"content,omitempty"`
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema,omitempty"`
+}
+
+type anthropicResponse struct {
+	ID         string           `json:"id"`
+	Type       string           `json:"type"`
+	Role       string           `json:"role"`
+	Model      string           `json:"model"`
+	Content    anthropicContent `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (r *anthropicRequest) toCanonical() (*ChatCompletionRequest, error) {
+	req := &ChatCompletionRequest{
+		Model:       r.Model,
+		MaxTokens:   r.MaxTokens,
+		Temperature: r.Temperature,
+		Stream:      r.Stream,
+		Stop:        r.StopSequences,
+	}
+
+	if len(r.System) > 0 {
+		var system anthropicContent
+		if err := json.Unmarshal(r.System, &system); err != nil {
+			return nil, fmt.Errorf("invalid anthropic system prompt: %w", err)
+		}
+		if text := blocksText(system); text != "" {
+			req.Messages = append(req.Messages, ChatMessage{Role: "system", Content: text})
+		}
+	}
+
+	for _, m := range r.Messages {
+		msg := ChatMessage{Role: m.Role}
+		var text []string
+		for _, b := range m.Content {
+			switch b.Type {
+			case "text":
+				text = append(text, b.Text)
+			case "tool_use":
+				msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+					ID:       b.ID,
+					Type:     "function",
+					Function: ToolCallFunction{Name: b.Name, Arguments: rawArguments(b.Input)},
+				})
+			case "tool_result":
+				// Each tool result becomes its own canonical tool message
+				req.Messages = append(req.Messages, ChatMessage{
+					Role:       "tool",
+					Content:    b.Content,
+					ToolCallID: b.ToolUseID,
+				})
+			}
+		}
+		if len(text) > 0 || len(msg.ToolCalls) > 0 {
+			msg.Content = strings.Join(text, "\n")
+			req.Messages = append(req.Messages, msg)
+		}
+	}
+
+	for _, t := range r.Tools {
+		req.Tools = append(req.Tools, Tool{
+			Type:     "function",
+			Function: ToolFunction{Name: t.Name, Description: t.Description, Parameters: t.InputSchema},
+		})
+	}
+	return req, nil
+}
+
+func anthropicRequestFromCanonical(req *ChatCompletionRequest) *anthropicRequest {
+	out := &anthropicRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		Temperature:   req.Temperature,
+		StopSequences: req.Stop,
+		Stream:        req.Stream,
+	}
+	if out.MaxTokens == 0 {
+		// max_tokens is required by the Messages API
+		out.MaxTokens = defaultAnthropicMaxTokens
+	}
+
+	var system []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			system = append(system, m.Content)
+		case "tool":
+			out.appendBlock("user", anthropicBlock{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content})
+		default:
+			if m.Content != "" {
+				out.appendBlock(m.Role, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				out.appendBlock(m.Role, anthropicBlock{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: argumentsRaw(tc.Function.Arguments),
+				})
+			}
+		}
+	}
+	if len(system) > 0 {
+		out.System, _ = json.Marshal(strings.Join(system, "\n\n"))
+	}
+
+	for _, t := range req.Tools {
+		out.Tools = append(out.Tools, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: t.Function.Parameters,
+		})
+	}
+	return out
+}
+
+// appendBlock adds a block to the last message if it has the same role,
+// since the Messages API requires user and assistant turns to alternate
+func (r *anthropicRequest) appendBlock(role string, block anthropicBlock) {
+	if n := len(r.Messages); n > 0 && r.Messages[n-1].Role == role {
+		r.Messages[n-1].Content = append(r.Messages[n-1].Content, block)
+		return
+	}
+	r.Messages = append(r.Messages, anthropicMessage{Role: role, Content: anthropicContent{block}})
+}
+
+func (r *anthropicResponse) toCanonical() *ChatCompletionResponse {
+	msg := ChatMessage{Role: "assistant"}
+	var text []string
+	for _, b := range r.Content {
+		switch b.Type {
+		case "text":
+			text = append(text, b.Text)
+		case "tool_use":
+			msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+				ID:       b.ID,
+				Type:     "function",
+				Function: ToolCallFunction{Name: b.Name, Arguments: rawArguments(b.Input)},
+			})
+		}
+	}
+	msg.Content = strings.Join(text, "\n")
+
+	resp := &ChatCompletionResponse{ID: r.ID, Object: "chat.completion", Model: r.Model}
+	resp.Choices = append(resp.Choices, newChoice(msg, anthropicFinishReason(r.StopReason)))
+	resp.Usage.PromptTokens = r.Usage.InputTokens
+	resp.Usage.CompletionTokens = r.Usage.OutputTokens
+	resp.Usage.TotalTokens = r.Usage.InputTokens + r.Usage.OutputTokens
+	return resp
+}
+
+func anthropicResponseFromCanonical(resp *ChatCompletionResponse) *anthropicResponse {
+	out := &anthropicResponse{ID: resp.ID, Type: "message", Role: "assistant", Model: resp.Model}
+	if len(resp.Choices) > 0 {
+		choice := resp.Choices[0]
+		if choice.Message.Content != "" {
+			out.Content = append(out.Content, anthropicBlock{Type: "text", Text: choice.Message.Content})
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			out.Content = append(out.Content, anthropicBlock{
+				Type:  "tool_use",
+				ID:    tc.ID,
+				Name:  tc.Function.Name,
+				Input: argumentsRaw(tc.Function.Arguments),
+			})
+		}
+		switch choice.Finish {
+		case FinishLength:
+			out.StopReason = "max_tokens"
+		case FinishToolCalls:
+			out.StopReason = "tool_use"
+		default:
+			out.StopReason = "end_turn"
+		}
+	}
+	out.Usage.InputTokens = resp.Usage.PromptTokens
+	out.Usage.OutputTokens = resp.Usage.CompletionTokens
+	return out
+}
+
+func anthropicFinishReason(reason string) string {
+	switch reason {
+	case "max_tokens":
+		return FinishLength
+	case "tool_use":
+		return FinishToolCalls
+	case "refusal":
+		return FinishContentFilter
+	default: // end_turn, stop_sequence
+		return FinishStop
+	}
+}
+
+// --- Gemini generateContent API ---
+
+type geminiRequest struct {
+	Contents          []geminiContent `json:"contents"`
+	SystemInstruction *geminiContent  `json:"systemInstruction,omitempty"`
+	GenerationConfig  *struct {
+		Temperature      float64  `json:"temperature,omitempty"`
+		MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+		StopSequences    []string `json:"stopSequences,omitempty"`
+		ResponseMimeType string   `json:"responseMimeType,omitempty"`
+	} `json:"generationConfig,omitempty"`
+	Tools []struct {
+		FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+	} `json:"tools,omitempty"`
+	SafetySettings []geminiSafetySetting `json:"safetySettings,omitempty"`
+	// Model is not part of the Gemini body (it is in the URL) but is kept
+	// so round trips through the canonical format preserve it
+	Model string `json:"model,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"` // user, model, function
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiResponse struct {
+	Candidates []struct {
+		Content      geminiContent `json:"content"`
+		FinishReason string        `json:"finishReason"`
+	} `json:"candidates"`
+	PromptFeedback *struct {
+		BlockReason string `json:"blockReason,omitempty"`
+	} `json:"promptFeedback,omitempty"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+		TotalTokenCount      int `json:"totalTokenCount"`
+	} `json:"usageMetadata"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	ResponseID   string `json:"responseId,omitempty"`
+}
+
+func (r *geminiRequest) toCanonical() *ChatCompletionRequest {
+	req := &ChatCompletionRequest{Model: r.Model}
+	if r.GenerationConfig != nil {
+		req.Temperature = r.GenerationConfig.Temperature
+		req.MaxTokens = r.GenerationConfig.MaxOutputTokens
+		req.Stop = r.GenerationConfig.StopSequences
+		if r.GenerationConfig.ResponseMimeType == "application/json" {
+			req.ResponseFormat = &ResponseFormat{Type: "json_object"}
+		}
+	}
+	if r.SystemInstruction != nil {
+		if text := partsText(r.SystemInstruction.Parts); text != "" {
+			req.Messages = append(req.Messages, ChatMessage{Role: "system", Content: text})
+		}
+	}
+
+	// Gemini has no call IDs; synthesize them so tool results can be paired
+	callIDs := map[string]string{}
+	for i, c := range r.Contents {
+		msg := ChatMessage{Role: "user"}
+		if c.Role == "model" {
+			msg.Role = "assistant"
+		}
+		var text []string
+		for j, p := range c.Parts {
+			switch {
+			case p.FunctionCall != nil:
+				id := fmt.Sprintf("call_%d_%d", i, j)
+				callIDs[p.FunctionCall.Name] = id
+				msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+					ID:       id,
+					Type:     "function",
+					Function: ToolCallFunction{Name: p.FunctionCall.Name, Arguments: rawArguments(p.FunctionCall.Args)},
+				})
+			case p.FunctionResponse != nil:
+				req.Messages = append(req.Messages, ChatMessage{
+					Role:       "tool",
+					Name:       p.FunctionResponse.Name,
+					Content:    string(p.FunctionResponse.Response),
+					ToolCallID: callIDs[p.FunctionResponse.Name],
+				})
+			case p.Text != "":
+				text = append(text, p.Text)
+			}
+		}
+		if len(text) > 0 || len(msg.ToolCalls) > 0 {
+			msg.Content = strings.Join(text, "\n")
+			req.Messages = append(req.Messages, msg)
+		}
+	}
+
+	for _, t := range r.Tools {
+		for _, fd := range t.FunctionDeclarations {
+			req.Tools = append(req.Tools, Tool{
+				Type:     "function",
+				Function: ToolFunction{Name: fd.Name, Description: fd.Description, Parameters: fd.Parameters},
+			})
+		}
+	}
+	return req
+}
+
+func geminiRequestFromCanonical(req *ChatCompletionRequest) *geminiRequest {
+	out := &geminiRequest{Model: req.Model}
+	if req.Temperature != 0 || req.MaxTokens != 0 || len(req.Stop) > 0 || req.ResponseFormat != nil {
+		out.GenerationConfig = &struct {
+			Temperature      float64  `json:"temperature,omitempty"`
+			MaxOutputTokens  int      `json:"maxOutputTokens,omitempty"`
+			StopSequences    []string `json:"stopSequences,omitempty"`
+			ResponseMimeType string   `json:"responseMimeType,omitempty"`
+		}{
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
+			StopSequences:   req.Stop,
+		}
+		if req.ResponseFormat.IsJSON() {
+			out.GenerationConfig.ResponseMimeType = "application/json"
+		}
+	}
+
+	callNames := map[string]string{}
+	var system []geminiPart
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			system = append(system, geminiPart{Text: m.Content})
+		case "tool":
+			name := m.Name
+			if name == "" {
+				name = callNames[m.ToolCallID]
+			}
+			out.appendPart("function", geminiPart{FunctionResponse: &geminiFunctionResponse{
+				Name:     name,
+				Response: toolResultRaw(m.Content),
+			}})
+		default:
+			role := "user"
+			if m.Role == "assistant" {
+				role = "model"
+			}
+			if m.Content != "" {
+				out.appendPart(role, geminiPart{Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				out.appendPart(role, geminiPart{FunctionCall: &geminiFunctionCall{
+					Name: tc.Function.Name,
+					Args: argumentsRaw(tc.Function.Arguments),
+				}})
+			}
+		}
+	}
+	if len(system) > 0 {
+		out.SystemInstruction = &geminiContent{Parts: system}
+	}
+
+	if len(req.Tools) > 0 {
+		decls := make([]geminiFunctionDeclaration, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			decls = append(decls, geminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+		out.Tools = append(out.Tools, struct {
+			FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations,omitempty"`
+		}{FunctionDeclarations: decls})
+	}
+	return out
+}
+
+func (r *geminiRequest) appendPart(role string, part geminiPart) {
+	if n := len(r.Contents); n > 0 && r.Contents[n-1].Role == role {
+		r.Contents[n-1].Parts = append(r.Contents[n-1].Parts, part)
+		return
+	}
+	r.Contents = append(r.Contents, geminiContent{Role: role, Parts: []geminiPart{part}})
+}
+
+func (r *geminiResponse) toCanonical() *ChatCompletionResponse {
+	resp := &ChatCompletionResponse{ID: r.ResponseID, Object: "chat.completion", Model: r.ModelVersion}
+	for i, c := range r.Candidates {
+		msg := ChatMessage{Role: "assistant"}
+		var text []string
+		for j, p := range c.Content.Parts {
+			if p.FunctionCall != nil {
+				msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+					ID:       fmt.Sprintf("call_%d_%d", i, j),
+					Type:     "function",
+					Function: ToolCallFunction{Name: p.FunctionCall.Name, Arguments: rawArguments(p.FunctionCall.Args)},
+				})
+			} else if p.Text != "" {
+				text = append(text, p.Text)
+			}
+		}
+		msg.Content = strings.Join(text, "")
+		finish := geminiFinishReason(c.FinishReason)
+		if len(msg.ToolCalls) > 0 && finish == FinishStop {
+			finish = FinishToolCalls
+		}
+		choice := newChoice(msg, finish)
+		choice.Index = i
+		resp.Choices = append(resp.Choices, choice)
+	}
+	// A blocked prompt gets no candidates, only the reason it was blocked
+	if len(resp.Choices) == 0 && r.PromptFeedback != nil && r.PromptFeedback.BlockReason != "" {
+		resp.Choices = append(resp.Choices, newChoice(ChatMessage{Role: "assistant"}, FinishContentFilter))
+	}
+	resp.Usage.PromptTokens = r.UsageMetadata.PromptTokenCount
+	resp.Usage.CompletionTokens = r.UsageMetadata.CandidatesTokenCount
+	resp.Usage.TotalTokens = r.UsageMetadata.TotalTokenCount
+	if resp.Usage.TotalTokens == 0 {
+		resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
+	}
+	return resp
+}
+
+func geminiResponseFromCanonical(resp *ChatCompletionResponse) *geminiResponse {
+	out := &geminiResponse{ModelVersion: resp.Model, ResponseID: resp.ID}
+	for _, choice := range resp.Choices {
+		content := geminiContent{Role: "model"}
+		if choice.Message.Content != "" {
+			content.Parts = append(content.Parts, geminiPart{Text: choice.Message.Content})
+		}
+		for _, tc := range choice.Message.ToolCalls {
+			content.Parts = append(content.Parts, geminiPart{FunctionCall: &geminiFunctionCall{
+				Name: tc.Function.Name,
+				Args: argumentsRaw(tc.Function.Arguments),
+			}})
+		}
+		finish := "STOP"
+		switch choice.Finish {
+		case FinishLength:
+			finish = "MAX_TOKENS"
+		case FinishContentFilter:
+			finish = "SAFETY"
+		}
+		out.Candidates = append(out.Candidates, struct {
+			Content      geminiContent `json:"content"`
+			FinishReason string        `json:"finishReason"`
+		}{Content: content, FinishReason: finish})
+	}
+	out.UsageMetadata.PromptTokenCount = resp.Usage.PromptTokens
+	out.UsageMetadata.CandidatesTokenCount = resp.Usage.CompletionTokens
+	out.UsageMetadata.TotalTokenCount = resp.Usage.TotalTokens
+	return out
+}
+
+func geminiFinishReason(reason string) string {
+	switch reason {
+	case "MAX_TOKENS":
+		return FinishLength
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return FinishContentFilter
+	default: // STOP, FINISH_REASON_UNSPECIFIED, OTHER
+		return FinishStop
+	}
+}
+
+// --- helpers ---
+
+func newChoice(msg ChatMessage, finish string) struct {
+	Index   int         `json:"index"`
+	Message ChatMessage `json:"message"`
+	Finish  string      `json:"finish_reason"`
+} {
+	return struct {
+		Index   int         `json:"index"`
+		Message ChatMessage `json:"message"`
+		Finish  string      `json:"finish_reason"`
+	}{Message: msg, Finish: finish}
+}
+
+func blocksText(blocks anthropicContent) string {
+	var text []string
+	for _, b := range blocks {
+		if b.Type == "text" && b.Text != "" {
+			text = append(text, b.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+func partsText(parts []geminiPart) string {
+	var text []string
+	for _, p := range parts {
+		if p.Text != "" {
+			text = append(text, p.Text)
+		}
+	}
+	return strings.Join(text, "\n")
+}
+
+// rawArguments converts a JSON object to the string-encoded arguments used
+// by the canonical format
+func rawArguments(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return "{}"
+	}
+	return string(raw)
+}
+
+// argumentsRaw converts canonical string arguments back to a JSON object,
+// wrapping non-JSON arguments so the output is always valid
+func argumentsRaw(args string) json.RawMessage {
+	if args == "" {
+		return json.RawMessage("{}")
+	}
+	if json.Valid([]byte(args)) {
+		return json.RawMessage(args)
+	}
+	wrapped, _ := json.Marshal(map[string]string{"input": args})
+	return wrapped
+}
+
+// toolResultRaw wraps a tool result for Gemini, which requires an object
+func toolResultRaw(content string) json.RawMessage {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	wrapped, _ := json.Marshal(map[string]string{"content": content})
+	return wrapped
+}
@@ -0,0 +1,188 @@
+package provider
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestToCanonicalRequest_Anthropic(t *testing.T) {
+	body := `{
+		"model": "claude-x",
+		"system": "be terse",
+		"max_tokens": 256,
+		"stop_sequences": ["END"],
+		"tools": [{"name": "read_file", "input_schema": {"type": "object"}}],
+		"messages": [
+			{"role": "user", "content": "read a.go"},
+			{"role": "assistant", "content": [
+				{"type": "text", "text": "reading"},
+				{"type": "tool_use", "id": "tu_1", "name": "read_file", "input": {"path": "a.go"}}
+			]},
+			{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "tu_1", "content": "package a"}]}
+		]
+	}`
+	req, err := ToCanonicalRequest(DialectAnthropic, []byte(body))
+	if err != nil {
+		t.Fatalf("ToCanonicalRequest failed: %v", err)
+	}
+	if len(req.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %+v", req.Messages)
+	}
+	if req.Messages[0].Role != "system" || req.Messages[0].Content != "be terse" {
+		t.Errorf("system message = %+v", req.Messages[0])
+	}
+	call := req.Messages[2].ToolCalls
+	if len(call) != 1 || call[0].ID != "tu_1" || call[0].Function.Arguments != `{"path": "a.go"}` {
+		t.Errorf("tool call = %+v", call)
+	}
+	if req.Messages[3].Role != "tool" || req.Messages[3].ToolCallID != "tu_1" {
+		t.Errorf("tool result = %+v", req.Messages[3])
+	}
+	if req.MaxTokens != 256 || len(req.Stop) != 1 || len(req.Tools) != 1 {
+		t.Errorf("unexpected request fields: %+v", req)
+	}
+}
+
+func TestConvertRequest_RoundTrip(t *testing.T) {
+	canonical := &ChatCompletionRequest{
+		Model:     "m",
+		MaxTokens: 100,
+		Messages: []ChatMessage{
+			{Role: "system", Content: "sys"},
+			{Role: "user", Content: "hi"},
+			{Role: "assistant", ToolCalls: []ToolCall{{ID: "c1", Type: "function", Function: ToolCallFunction{Name: "ls", Arguments: `{"dir":"."}`}}}},
+			{Role: "tool", ToolCallID: "c1", Content: `{"files":["a"]}`},
+		},
+	}
+	for _, dialect := range []string{DialectAnthropic, DialectGemini} {
+		t.Run(dialect, func(t *testing.T) {
+			body, err := FromCanonicalRequest(dialect, canonical)
+			if err != nil {
+				t.Fatalf("FromCanonicalRequest failed: %v", err)
+			}
+			if got := DetectRequestDialect(body); got != dialect {
+				t.Errorf("DetectRequestDialect = %q, want %q", got, dialect)
+			}
+			back, err := ToCanonicalRequest(dialect, body)
+			if err != nil {
+				t.Fatalf("ToCanonicalRequest failed: %v", err)
+			}
+			if len(back.Messages) != len(canonical.Messages) {
+				t.Fatalf("messages = %+v", back.Messages)
+			}
+			for i, m := range back.Messages {
+				if m.Role != canonical.Messages[i].Role {
+					t.Errorf("message %d role = %q, want %q", i, m.Role, canonical.Messages[i].Role)
+				}
+			}
+			if back.Messages[2].ToolCalls[0].Function.Name != "ls" {
+				t.Errorf("tool call lost: %+v", back.Messages[2])
+			}
+			if back.Messages[3].ToolCallID != back.Messages[2].ToolCalls[0].ID {
+				t.Errorf("tool result not paired with call: %+v", back.Messages[3])
+			}
+		})
+	}
+}
+
+func TestToCanonicalResponse_FinishReasons(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect string
+		body    string
+		finish  string
+		content string
+		tokens  int
+	}{
+		{
+			name:    "anthropic end_turn",
+			dialect: DialectAnthropic,
+			body:    `{"id":"msg_1","content":[{"type":"text","text":"done"}],"stop_reason":"end_turn","usage":{"input_tokens":10,"output_tokens":2}}`,
+			finish:  FinishStop,
+			content: "done",
+			tokens:  12,
+		},
+		{
+			name:    "anthropic tool_use",
+			dialect: DialectAnthropic,
+			body:    `{"content":[{"type":"tool_use","id":"t","name":"ls","input":{}}],"stop_reason":"tool_use"}`,
+			finish:  FinishToolCalls,
+		},
+		{
+			name:    "gemini max tokens",
+			dialect: DialectGemini,
+			body:    `{"candidates":[{"content":{"role":"model","parts":[{"text":"par"},{"text":"tial"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":3,"totalTokenCount":8}}`,
+			finish:  FinishLength,
+			content: "partial",
+			tokens:  8,
+		},
+		{
+			name:    "gemini safety",
+			dialect: DialectGemini,
+			body:    `{"candidates":[{"content":{"parts":[]},"finishReason":"SAFETY"}]}`,
+			finish:  FinishContentFilter,
+		},
+		{
+			name:    "gemini function call",
+			dialect: DialectGemini,
+			body:    `{"candidates":[{"content":{"parts":[{"functionCall":{"name":"ls","args":{}}}]},"finishReason":"STOP"}]}`,
+			finish:  FinishToolCalls,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := ToCanonicalResponse(tt.dialect, []byte(tt.body))
+			if err != nil {
+				t.Fatalf("ToCanonicalResponse failed: %v", err)
+			}
+			if len(resp.Choices) != 1 {
+				t.Fatalf("expected 1 choice, got %d", len(resp.Choices))
+			}
+			if resp.Choices[0].Finish != tt.finish {
+				t.Errorf("finish = %q, want %q", resp.Choices[0].Finish, tt.finish)
+			}
+			if resp.Choices[0].Message.Content != tt.content {
+				t.Errorf("content = %q, want %q", resp.Choices[0].Message.Content, tt.content)
+			}
+			if resp.Usage.TotalTokens != tt.tokens {
+				t.Errorf("total tokens = %d, want %d", resp.Usage.TotalTokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestConvertResponse_AnthropicToGemini(t *testing.T) {
+	body := `{"content":[{"type":"text","text":"hello"}],"stop_reason":"max_tokens","usage":{"input_tokens":1,"output_tokens":1}}`
+	out, err := ConvertResponse(DialectAnthropic, DialectGemini, []byte(body))
+	if err != nil {
+		t.Fatalf("ConvertResponse failed: %v", err)
+	}
+	var gemini geminiResponse
+	if err := json.Unmarshal(out, &gemini); err != nil {
+		t.Fatalf("invalid gemini output: %v", err)
+	}
+	if len(gemini.Candidates) != 1 || gemini.Candidates[0].FinishReason != "MAX_TOKENS" {
+		t.Errorf("unexpected candidates: %+v", gemini.Candidates)
+	}
+	if gemini.Candidates[0].Content.Parts[0].Text != "hello" {
+		t.Errorf("text lost: %+v", gemini.Candidates[0].Content)
+	}
+}
+
+func TestCanonicalizeRequestBody(t *testing.T) {
+	openai := `{"model":"m","prompt":"raw"}`
+	if got := CanonicalizeRequestBody(openai); got != openai {
+		t.Errorf("openai body should be unchanged, got %s", got)
+	}
+	if got := CanonicalizeRequestBody("not json"); got != "not json" {
+		t.Errorf("unparseable body should be unchanged, got %s", got)
+	}
+
+	anthropic := `{"model":"m","system":"s","max_tokens":10,"messages":[{"role":"user","content":"hi"}]}`
+	gemini := `{"model":"m","systemInstruction":{"parts":[{"text":"s"}]},"generationConfig":{"maxOutputTokens":10},"contents":[{"role":"user","parts":[{"text":"hi"}]}]}`
+	a := CanonicalizeRequestBody(anthropic)
+	g := CanonicalizeRequestBody(gemini)
+	if a != g {
+		t.Errorf("equivalent requests should canonicalize identically:\n%s\n%s", a, g)
+	}
+}
@@ -0,0 +1,303 @@
+package provider
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/plugin"
+)
+
+// DefaultGeminiEndpoint is the Gemini API base URL used when a provider
+// configures none
+const DefaultGeminiEndpoint = "https://generativelanguage.googleapis.com/v1beta"
+
+// geminiHarmCategories are the categories safety settings apply to
+var geminiHarmCategories = []string{
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+}
+
+// geminiSafetyThresholds maps the safety levels of an endpoint to Gemini
+// block thresholds
+var geminiSafetyThresholds = map[string]string{
+	"none":   "BLOCK_NONE",
+	"high":   "BLOCK_ONLY_HIGH",
+	"medium": "BLOCK_MEDIUM_AND_ABOVE",
+	"low":    "BLOCK_LOW_AND_ABOVE",
+}
+
+// GeminiProvider implements Protocol for the Google Gemini API, sending
+// requests to generateContent and streamGenerateContent with tools as
+// native function declarations.
+//
+// The endpoint is the API base URL; its query may set safety to none, high,
+// medium or low, the level of harm every request blocks at:
+//
+//	https://generativelanguage.googleapis.com/v1beta?safety=high
+type GeminiProvider struct {
+	endpoint        string
+	apiKey          string
+	model           string // Used when a request names no model
+	safety          []geminiSafetySetting
+	client          *http.Client
+	streamingClient *http.Client
+}
+
+// NewGeminiProvider creates a Gemini provider. model is the model requests
+// without a model go to.
+func NewGeminiProvider(endpoint, apiKey, model string) (*GeminiProvider, error) {
+	endpoint = strings.TrimSpace(endpoint)
+	if endpoint == "" {
+		endpoint = DefaultGeminiEndpoint
+	}
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid gemini endpoint %q", endpoint)
+	}
+	p := &GeminiProvider{
+		endpoint:        u.Scheme + "://" + u.Host + strings.TrimSuffix(u.Path, "/"),
+		apiKey:          apiKey,
+		model:           strings.TrimPrefix(model, "models/"),
+		client:          &http.Client{Timeout: 15 * time.Minute},
+		streamingClient: &http.Client{},
+	}
+	if level := u.Query().Get("safety"); level != "" {
+		threshold, ok := geminiSafetyThresholds[strings.ToLower(level)]
+		if !ok {
+			return nil, fmt.Errorf("invalid gemini safety level %q: expected none, high, medium or low", level)
+		}
+		for _, category := range geminiHarmCategories {
+			p.safety = append(p.safety, geminiSafetySetting{Category: category, Threshold: threshold})
+		}
+	}
+	return p, nil
+}
+
+// modelURL returns the URL of a method on the request's model
+func (p *GeminiProvider) modelURL(model, method string) string {
+	if model == "" {
+		model = p.model
+	}
+	return fmt.Sprintf("%s/models/%s:%s", p.endpoint, url.PathEscape(strings.TrimPrefix(model, "models/")), method)
+}
+
+// body encodes a request in the generateContent format
+func (p *GeminiProvider) body(req *ChatCompletionRequest) ([]byte, error) {
+	out := geminiRequestFromCanonical(req)
+	out.Model = "" // The model is in the URL
+	out.SafetySettings = p.safety
+	return json.Marshal(out)
+}
+
+// CreateChatCompletion generates content with the request's model
+func (p *GeminiProvider) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	body, err := p.body(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	resp, err := p.do(ctx, p.client, http.MethodPost, p.modelURL(req.Model, "generateContent"), body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, chatCompletionError(req, resp.StatusCode, string(respBody))
+	}
+
+	var out geminiResponse
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	completion := out.toCanonical()
+	if completion.Model == "" {
+		completion.Model = req.Model
+		if completion.Model == "" {
+			completion.Model = p.model
+		}
+	}
+	return completion, nil
+}
+
+// CreateChatCompletionStream streams generated content over server-sent
+// events. Chunks carry text only; requests expecting tool calls should not
+// stream.
+func (p *GeminiProvider) CreateChatCompletionStream(ctx context.Context, req *ChatCompletionRequest, handler StreamHandler) error {
+	body, err := p.body(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	resp, err := p.do(ctx, p.streamingClient, http.MethodPost, p.modelURL(req.Model, "streamGenerateContent")+"?alt=sse", body)
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("request cancelled: %w", ctx.Err())
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return chatCompletionError(req, resp.StatusCode, string(respBody))
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	chunksReceived := 0
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return fmt.Errorf("stream interrupted after %d chunks: %w", chunksReceived, ctx.Err())
+		}
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		var event geminiResponse
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			continue
+		}
+		chunksReceived++
+		if err := handler(event.toStreamChunk()); err != nil {
+			return fmt.Errorf("handler error after %d chunks: %w", chunksReceived, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream connection lost after %d chunks: %w", chunksReceived, err)
+	}
+	return nil
+}
+
+// toStreamChunk converts one streamed response to a chunk of text deltas
+func (r *geminiResponse) toStreamChunk() *StreamChunk {
+	chunk := &StreamChunk{ID: r.ResponseID, Object: "chat.completion.chunk", Model: r.ModelVersion}
+	for i, c := range r.Candidates {
+		var choice struct {
+			Index int `json:"index"`
+			Delta struct {
+				Role    string `json:"role,omitempty"`
+				Content string `json:"content,omitempty"`
+			} `json:"delta"`
+			FinishReason string `json:"finish_reason,omitempty"`
+		}
+		choice.Index = i
+		choice.Delta.Role = "assistant"
+		for _, part := range c.Content.Parts {
+			choice.Delta.Content += part.Text
+		}
+		// Only the last chunk of a candidate has a finish reason
+		if c.FinishReason != "" {
+			choice.FinishReason = geminiFinishReason(c.FinishReason)
+		}
+		chunk.Choices = append(chunk.Choices, choice)
+	}
+	return chunk
+}
+
+// GetModels lists the models that generate content, or only the configured
+// model when there is one
+func (p *GeminiProvider) GetModels(ctx context.Context) ([]Model, error) {
+	resp, err := p.do(ctx, p.client, http.MethodGet, p.endpoint+"/models?pageSize=1000", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+	var list struct {
+		Models []struct {
+			Name                       string   `json:"name"`
+			InputTokenLimit            int      `json:"inputTokenLimit"`
+			SupportedGenerationMethods []string `json:"supportedGenerationMethods"`
+		} `json:"models"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	var models []Model
+	for _, m := range list.Models {
+		id := strings.TrimPrefix(m.Name, "models/")
+		if !slices.Contains(m.SupportedGenerationMethods, "generateContent") {
+			continue
+		}
+		if p.model != "" && id != p.model {
+			continue
+		}
+		models = append(models, Model{ID: id, Object: "model", OwnedBy: "google", MaxModelLen: m.InputTokenLimit})
+	}
+	if p.model != "" && len(models) == 0 {
+		return nil, fmt.Errorf("gemini model %s is not available", p.model)
+	}
+	return models, nil
+}
+
+// Capabilities reports what a Gemini model supports. Model names carry no
+// parameter count, so the tier complexity routing ranks them by is
+// declared from the model's line.
+func (p *GeminiProvider) Capabilities(model string) plugin.Capabilities {
+	if model == "" {
+		model = p.model
+	}
+	return plugin.Capabilities{
+		Streaming:          true,
+		FunctionCalling:    true,
+		CustomCapabilities: map[string]bool{TierCapability(GeminiModelTier(model)): true},
+	}
+}
+
+// GeminiModelTier returns the tier of a Gemini model from its line: Pro
+// and Ultra models are the largest, Flash-Lite and Nano the smallest
+func GeminiModelTier(model string) ModelTier {
+	name := strings.ToLower(model)
+	switch {
+	case strings.Contains(name, "-ultra"), strings.Contains(name, "-pro"):
+		return TierXLarge
+	case strings.Contains(name, "nano"), strings.HasSuffix(name, "-8b"), strings.Contains(name, "-8b-"):
+		return TierSmall
+	case strings.Contains(name, "flash-lite"):
+		return TierMedium
+	default: // flash
+		return TierLarge
+	}
+}
+
+// do sends a request authenticated with the API key
+func (p *GeminiProvider) do(ctx context.Context, client *http.Client, method, u string, body []byte) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	if p.apiKey != "" {
+		httpReq.Header.Set("x-goog-api-key", p.apiKey)
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	return resp, nil
+}
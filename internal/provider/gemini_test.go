@@ -0,0 +1,177 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGeminiProvider_ToolCalling(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1beta/models/gemini-2.5-pro:generateContent" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("x-goog-api-key"); got != "secret" {
+			t.Errorf("expected API key header, got %q", got)
+		}
+		var body geminiRequest
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model != "" {
+			t.Errorf("expected no model in the body, got %q", body.Model)
+		}
+		if len(body.Tools) != 1 || body.Tools[0].FunctionDeclarations[0].Name != "read_file" {
+			t.Errorf("expected a function declaration, got %+v", body.Tools)
+		}
+		if len(body.SafetySettings) != len(geminiHarmCategories) || body.SafetySettings[0].Threshold != "BLOCK_ONLY_HIGH" {
+			t.Errorf("unexpected safety settings %+v", body.SafetySettings)
+		}
+		if body.SystemInstruction == nil || body.SystemInstruction.Parts[0].Text != "be brief" {
+			t.Errorf("expected a system instruction, got %+v", body.SystemInstruction)
+		}
+		fmt.Fprint(w, `{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"read_file","args":{"path":"main.go"}}}]},"finishReason":"STOP"}],
+			"usageMetadata":{"promptTokenCount":12,"candidatesTokenCount":5,"totalTokenCount":17}}`)
+	}))
+	defer server.Close()
+
+	p, err := NewGeminiProvider(server.URL+"/v1beta?safety=high", "secret", "gemini-2.5-pro")
+	if err != nil {
+		t.Fatalf("NewGeminiProvider: %v", err)
+	}
+	resp, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+		Messages: []ChatMessage{{Role: "system", Content: "be brief"}, {Role: "user", Content: "read main.go"}},
+		Tools: []Tool{{Type: "function", Function: ToolFunction{
+			Name: "read_file", Parameters: json.RawMessage(`{"type":"object","properties":{"path":{"type":"string"}}}`),
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	choice := resp.Choices[0]
+	if choice.Finish != FinishToolCalls || len(choice.Message.ToolCalls) != 1 || choice.Message.ToolCalls[0].Function.Arguments != `{"path":"main.go"}` {
+		t.Errorf("expected a read_file call, got %+v", choice)
+	}
+	if resp.Usage.TotalTokens != 17 || resp.Model != "gemini-2.5-pro" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGeminiProvider_BlockedPrompt(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"promptFeedback":{"blockReason":"SAFETY"},"usageMetadata":{"promptTokenCount":4}}`)
+	}))
+	defer server.Close()
+
+	p, _ := NewGeminiProvider(server.URL, "", "gemini-2.5-flash")
+	resp, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "x"}}})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].Finish != FinishContentFilter {
+		t.Errorf("expected a content filter finish, got %+v", resp.Choices)
+	}
+}
+
+func TestGeminiProvider_Stream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/models/gemini-2.5-flash:streamGenerateContent" || r.URL.Query().Get("alt") != "sse" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hel\"}]}}]}\n\n")
+		fmt.Fprint(w, "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"lo\"}]},\"finishReason\":\"STOP\"}]}\n\n")
+	}))
+	defer server.Close()
+
+	p, _ := NewGeminiProvider(server.URL, "", "gemini-2.5-flash")
+	var text strings.Builder
+	var finishes []string
+	err := p.CreateChatCompletionStream(context.Background(), &ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: "hi"}}}, func(chunk *StreamChunk) error {
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		finishes = append(finishes, chunk.Choices[0].FinishReason)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletionStream: %v", err)
+	}
+	if text.String() != "Hello" || len(finishes) != 2 || finishes[0] != "" || finishes[1] != FinishStop {
+		t.Errorf("unexpected stream %q, finishes %v", text.String(), finishes)
+	}
+}
+
+func TestGeminiProvider_GetModels(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"models":[
+			{"name":"models/gemini-2.5-pro","inputTokenLimit":1048576,"supportedGenerationMethods":["generateContent","countTokens"]},
+			{"name":"models/text-embedding-004","supportedGenerationMethods":["embedContent"]}]}`)
+	}))
+	defer server.Close()
+
+	p, _ := NewGeminiProvider(server.URL, "", "")
+	models, err := p.GetModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "gemini-2.5-pro" || models[0].MaxModelLen != 1048576 {
+		t.Fatalf("expected only the generating model, got %+v, %v", models, err)
+	}
+
+	p, _ = NewGeminiProvider(server.URL, "", "gemini-1.0-ultra")
+	if _, err := p.GetModels(context.Background()); err == nil {
+		t.Error("expected an error for a model the API does not offer")
+	}
+}
+
+func TestNewGeminiProvider(t *testing.T) {
+	p, err := NewGeminiProvider("", "", "models/gemini-2.5-pro")
+	if err != nil || p.endpoint != DefaultGeminiEndpoint || p.model != "gemini-2.5-pro" {
+		t.Errorf("expected the default endpoint and a bare model name, got %+v, %v", p, err)
+	}
+	if _, err := NewGeminiProvider(DefaultGeminiEndpoint+"?safety=some", "", ""); err == nil {
+		t.Error("expected an error for an unknown safety level")
+	}
+}
+
+func TestGeminiModelTier(t *testing.T) {
+	cases := map[string]ModelTier{
+		"gemini-2.5-pro":           TierXLarge,
+		"gemini-2.5-flash":         TierLarge,
+		"gemini-2.0-flash-lite":    TierMedium,
+		"gemini-1.5-flash-8b":      TierSmall,
+		"gemini-2.0-flash-preview": TierLarge,
+	}
+	for model, want := range cases {
+		if got := GeminiModelTier(model); got != want {
+			t.Errorf("GeminiModelTier(%s) = %s, want %s", model, got, want)
+		}
+	}
+}
+
+func TestRegistryDeclaredTier(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(&ProviderConfig{ID: "gemini", Type: TypeGemini, Model: "gemini-2.5-pro", Status: "active"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := r.Register(&ProviderConfig{ID: "small", Type: "openai", Endpoint: "http://localhost/v1", Model: "qwen-7b", Status: "active"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	caps, ok := r.Capabilities("gemini")
+	if !ok || !caps.FunctionCalling || !caps.Streaming {
+		t.Fatalf("expected Gemini capabilities, got %+v, %v", caps, ok)
+	}
+	if tier, ok := CapabilityTier(caps); !ok || tier != TierXLarge {
+		t.Errorf("expected a declared xlarge tier, got %s, %v", tier, ok)
+	}
+	if _, ok := r.Capabilities("small"); ok {
+		t.Error("expected no capabilities from an OpenAI-compatible provider")
+	}
+
+	r.UpdateProviderScore("gemini", 0, 0)
+	r.UpdateProviderScore("small", 7, 0)
+	if best, _, ok := r.SelectProviderForComplexity(ComplexityExtended); !ok || best.Config.ID != "gemini" {
+		t.Errorf("expected Gemini for extended work, got %+v", best)
+	}
+	if best, _, ok := r.SelectProviderForComplexity(ComplexitySimple); !ok || best.Config.ID != "small" {
+		t.Errorf("expected the small model for simple work, got %+v", best)
+	}
+}
@@ -111,6 +111,35 @@ func TestMockProvider_CreateChatCompletion_UsageCounts(t *testing.T) {
 	}
 }
 
+func TestMockProvider_CreateChatCompletion_Scripted(t *testing.T) {
+	p := NewScriptedMockProvider(func(req *ChatCompletionRequest) (string, bool) {
+		if req.Messages[0].Content == "scripted" {
+			return `{"actions":[{"type":"done"}]}`, true
+		}
+		return "", false
+	})
+
+	resp, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "scripted"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != `{"actions":[{"type":"done"}]}` {
+		t.Errorf("scripted reply = %q", got)
+	}
+
+	resp, err = p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+		Messages: []ChatMessage{{Role: "user", Content: "other"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "[mock] other" {
+		t.Errorf("unscripted reply = %q, want the echo", got)
+	}
+}
+
 func TestMockProvider_CreateChatCompletion_ResponseFields(t *testing.T) {
 	p := NewMockProvider()
 
@@ -7,14 +7,37 @@ import (
 
 // MockProvider is an in-memory provider that returns canned responses.
 // It is useful for local development and smoke-testing when no real model endpoint is available.
-type MockProvider struct{}
+type MockProvider struct {
+	respond MockResponder
+}
+
+// MockResponder scripts a MockProvider's replies. It returns false for
+// requests it has no script for, which get the usual echo.
+type MockResponder func(req *ChatCompletionRequest) (string, bool)
 
 func NewMockProvider() *MockProvider {
 	return &MockProvider{}
 }
 
-// CreateChatCompletion returns a static echo response.
+// NewScriptedMockProvider creates a MockProvider that answers with respond
+// when it can, such as the demo mode's scripted agents.
+func NewScriptedMockProvider(respond MockResponder) *MockProvider {
+	return &MockProvider{respond: respond}
+}
+
+// CreateChatCompletion returns the scripted reply if there is one, otherwise
+// a static echo response.
 func (p *MockProvider) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	if p.respond != nil {
+		if reply, ok := p.respond(req); ok {
+			prompt := 0
+			for _, m := range req.Messages {
+				prompt += len(m.Content)
+			}
+			return mockCompletion(req.Model, reply, prompt), nil
+		}
+	}
+
 	// Build a short echo message from the last user content.
 	content := "mock response"
 	if len(req.Messages) > 0 {
@@ -24,11 +47,16 @@ func (p *MockProvider) CreateChatCompletion(ctx context.Context, req *ChatComple
 		}
 	}
 
+	return mockCompletion(req.Model, "[mock] "+content, len(content)), nil
+}
+
+// mockCompletion wraps content in a completion response
+func mockCompletion(model, content string, promptTokens int) *ChatCompletionResponse {
 	resp := &ChatCompletionResponse{
 		ID:      "mock-completion",
 		Object:  "chat.completion",
 		Created: time.Now().Unix(),
-		Model:   req.Model,
+		Model:   model,
 		Choices: []struct {
 			Index   int         `json:"index"`
 			Message ChatMessage `json:"message"`
@@ -38,16 +66,16 @@ func (p *MockProvider) CreateChatCompletion(ctx context.Context, req *ChatComple
 				Index: 0,
 				Message: ChatMessage{
 					Role:    "assistant",
-					Content: "[mock] " + content,
+					Content: content,
 				},
 				Finish: "stop",
 			},
 		},
 	}
-	resp.Usage.PromptTokens = len(content)
-	resp.Usage.CompletionTokens = len(resp.Choices[0].Message.Content)
+	resp.Usage.PromptTokens = promptTokens
+	resp.Usage.CompletionTokens = len(content)
 	resp.Usage.TotalTokens = resp.Usage.PromptTokens + resp.Usage.CompletionTokens
-	return resp, nil
+	return resp
 }
 
 // GetModels returns a single mock model.
@@ -0,0 +1,130 @@
+package provider
+
+import (
+	"context"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+// minShapedOutputTokens is the smallest completion budget request shaping
+// will leave when clamping max_tokens to the remaining context window
+const minShapedOutputTokens = 256
+
+// ModelMetadataSource supplies per-model limits, capabilities and pricing
+type ModelMetadataSource interface {
+	ModelMetadata(model string) (internalmodels.ModelMetadata, bool)
+}
+
+// SetModelMetadata configures the metadata used for request shaping and
+// cost estimates
+func (r *Registry) SetModelMetadata(source ModelMetadataSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.metadata = source
+}
+
+// ModelMetadata returns metadata for a model, if a source is configured
+// and knows it
+func (r *Registry) ModelMetadata(model string) (internalmodels.ModelMetadata, bool) {
+	r.mu.RLock()
+	source := r.metadata
+	r.mu.RUnlock()
+	if source == nil || model == "" {
+		return internalmodels.ModelMetadata{}, false
+	}
+	return source.ModelMetadata(model)
+}
+
+// Pricer prices requests from a pricing catalog, reporting false for
+// models it has no price for
+type Pricer interface {
+	Cost(providerID, model string, promptTokens, completionTokens, cachedTokens int64) (float64, bool)
+}
+
+// SetPricer configures the pricing catalog cost estimates consult first
+func (r *Registry) SetPricer(p Pricer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pricer = p
+}
+
+// EstimateCost returns the USD cost of a request, using the pricing
+// catalog, then the model's per-token prices from its metadata, and the
+// provider's flat rate otherwise
+func (r *Registry) EstimateCost(providerID, model string, promptTokens, completionTokens int) float64 {
+	r.mu.RLock()
+	pricer := r.pricer
+	r.mu.RUnlock()
+	if pricer != nil {
+		if cost, ok := pricer.Cost(providerID, r.modelOf(providerID, model), int64(promptTokens), int64(completionTokens), 0); ok {
+			return cost
+		}
+	}
+	if md, ok := r.ModelMetadata(model); ok && md.HasPricing() {
+		return md.Cost(promptTokens, completionTokens)
+	}
+	registered, err := r.Get(providerID)
+	if err != nil || registered.Config == nil {
+		return 0
+	}
+	return float64(promptTokens+completionTokens) * registered.Config.CostPerMToken / 1e6
+}
+
+// modelOf returns model, or the provider's model when the caller does not
+// know which model served a request
+func (r *Registry) modelOf(providerID, model string) string {
+	if model != "" {
+		return model
+	}
+	if registered, err := r.Get(providerID); err == nil && registered.Config != nil {
+		return registered.Config.Model
+	}
+	return ""
+}
+
+// shapeRequest fits a request to the model's known limits: max_tokens is
+// clamped to the output limit and the remaining context window, tools or
+// JSON mode are dropped for models known not to support them, and a JSON
+// schema falls back to plain JSON mode for models without schema support.
+func (r *Registry) shapeRequest(ctx context.Context, providerID string, req *ChatCompletionRequest) {
+	md, ok := r.ModelMetadata(req.Model)
+	if !ok {
+		return
+	}
+
+	if md.MaxOutputTokens > 0 && req.MaxTokens > md.MaxOutputTokens {
+		req.MaxTokens = md.MaxOutputTokens
+	}
+	if md.ContextWindow > 0 && req.MaxTokens > 0 {
+		remaining := md.ContextWindow - estimatePromptTokens(req.Messages)
+		if remaining < minShapedOutputTokens {
+			remaining = minShapedOutputTokens
+		}
+		if req.MaxTokens > remaining {
+			req.MaxTokens = remaining
+		}
+	}
+	if md.SupportsTools != nil && !*md.SupportsTools && len(req.Tools) > 0 {
+		providerLog.DebugContext(ctx, "model does not support tools, dropping tool definitions",
+			"provider_id", providerID, "model", req.Model, "tools", len(req.Tools))
+		req.Tools = nil
+	}
+	if md.SupportsJSONSchema != nil && !*md.SupportsJSONSchema && req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema" {
+		req.ResponseFormat = &ResponseFormat{Type: "json_object"}
+	}
+	if md.SupportsJSONMode != nil && !*md.SupportsJSONMode && req.ResponseFormat != nil {
+		req.ResponseFormat = nil
+	}
+}
+
+// estimatePromptTokens approximates prompt size at 4 characters per token
+func estimatePromptTokens(messages []ChatMessage) int {
+	total := 0
+	for _, m := range messages {
+		total += len(m.Content) / 4
+		for _, tc := range m.ToolCalls {
+			total += len(tc.Function.Arguments) / 4
+		}
+	}
+	return total
+}
@@ -0,0 +1,72 @@
+package provider
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+)
+
+type staticMetadata map[string]internalmodels.ModelMetadata
+
+func (s staticMetadata) ModelMetadata(model string) (internalmodels.ModelMetadata, bool) {
+	md, ok := s[model]
+	return md, ok
+}
+
+func TestShapeRequest(t *testing.T) {
+	no := false
+	r := NewRegistry()
+	r.SetModelMetadata(staticMetadata{
+		"small": {Model: "small", ContextWindow: 4096, MaxOutputTokens: 2048, SupportsTools: &no, SupportsJSONMode: &no},
+	})
+
+	req := &ChatCompletionRequest{
+		Model:          "small",
+		MaxTokens:      8000,
+		Messages:       []ChatMessage{{Role: "user", Content: strings.Repeat("x", 4*3000)}},
+		Tools:          []Tool{{Type: "function", Function: ToolFunction{Name: "ls"}}},
+		ResponseFormat: &ResponseFormat{Type: "json_object"},
+	}
+	r.shapeRequest(context.Background(), "p1", req)
+	if req.MaxTokens != 1096 {
+		t.Errorf("MaxTokens = %d, want 1096 (context window minus prompt)", req.MaxTokens)
+	}
+	if req.Tools != nil || req.ResponseFormat != nil {
+		t.Error("unsupported tools and JSON mode should be dropped")
+	}
+
+	yes := true
+	r.SetModelMetadata(staticMetadata{
+		"json-only": {Model: "json-only", SupportsJSONMode: &yes, SupportsJSONSchema: &no},
+	})
+	schemaReq := &ChatCompletionRequest{Model: "json-only", ResponseFormat: SchemaFormat("s", []byte(`{}`))}
+	r.shapeRequest(context.Background(), "p1", schemaReq)
+	if schemaReq.ResponseFormat == nil || schemaReq.ResponseFormat.Type != "json_object" {
+		t.Errorf("an unsupported schema should fall back to JSON mode, got %+v", schemaReq.ResponseFormat)
+	}
+
+	unknown := &ChatCompletionRequest{Model: "other", MaxTokens: 8000}
+	r.shapeRequest(context.Background(), "p1", unknown)
+	if unknown.MaxTokens != 8000 {
+		t.Errorf("unknown models should not be shaped, got %d", unknown.MaxTokens)
+	}
+}
+
+func TestEstimateCost(t *testing.T) {
+	r := NewRegistry()
+	r.SetModelMetadata(staticMetadata{
+		"priced": {Model: "priced", InputCostPerMToken: 2, OutputCostPerMToken: 8},
+	})
+	if err := r.Register(&ProviderConfig{ID: "p1", Type: "mock", Model: "flat", CostPerMToken: 1}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+
+	if got := r.EstimateCost("p1", "priced", 1_000_000, 1_000_000); got != 10 {
+		t.Errorf("per-token pricing cost = %v, want 10", got)
+	}
+	if got := r.EstimateCost("p1", "flat", 500_000, 500_000); got != 1 {
+		t.Errorf("flat rate cost = %v, want 1", got)
+	}
+}
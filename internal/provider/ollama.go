@@ -81,8 +81,8 @@ func (p *OllamaProvider) CreateChatCompletion(ctx context.Context, req *ChatComp
 			Role    string `json:"role"`
 			Content string `json:"content"`
 		} `json:"messages"`
-		Stream  bool   `json:"stream"`
-		Format  string `json:"format,omitempty"`
+		Stream  bool            `json:"stream"`
+		Format  json.RawMessage `json:"format,omitempty"` // "json", or a JSON schema
 		Options struct {
 			Temperature float64 `json:"temperature,omitempty"`
 		} `json:"options,omitempty"`
@@ -91,8 +91,11 @@ func (p *OllamaProvider) CreateChatCompletion(ctx context.Context, req *ChatComp
 		Stream: false,
 	}
 	ollamaReq.Options.Temperature = req.Temperature
-	if req.ResponseFormat != nil && req.ResponseFormat.Type == "json_object" {
-		ollamaReq.Format = "json"
+	switch {
+	case req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema" && req.ResponseFormat.JSONSchema != nil:
+		ollamaReq.Format = req.ResponseFormat.JSONSchema.Schema
+	case req.ResponseFormat.IsJSON():
+		ollamaReq.Format = json.RawMessage(`"json"`)
 	}
 	for _, msg := range req.Messages {
 		ollamaReq.Messages = append(ollamaReq.Messages, struct {
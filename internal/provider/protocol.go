@@ -64,15 +64,87 @@ type StreamingProtocol interface {
 
 // ChatMessage represents a message in the chat
 type ChatMessage struct {
-	Role    string `json:"role"`    // system, user, assistant
-	Content string `json:"content"` // message content
+	Role       string     `json:"role"`                   // system, user, assistant, tool
+	Content    string     `json:"content"`                // message content
+	Name       string     `json:"name,omitempty"`         // tool name for role=tool
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // tool invocations requested by the assistant
+	ToolCallID string     `json:"tool_call_id,omitempty"` // call answered by a role=tool message
+}
+
+// ToolCall is a function call requested by the model
+type ToolCall struct {
+	ID       string           `json:"id"`
+	Type     string           `json:"type"` // always "function"
+	Function ToolCallFunction `json:"function"`
+}
+
+// ToolCallFunction names the function and carries its JSON-encoded arguments
+type ToolCallFunction struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// Tool declares a function the model may call
+type Tool struct {
+	Type     string       `json:"type"` // always "function"
+	Function ToolFunction `json:"function"`
+}
+
+// ToolFunction describes a callable function and its JSON Schema parameters
+type ToolFunction struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
 }
 
 // ResponseFormat specifies the output format for the LLM response.
 // Setting Type to "json_object" enables constrained JSON decoding in
-// vLLM and OpenAI-compatible APIs, guaranteeing valid JSON output.
+// vLLM and OpenAI-compatible APIs, guaranteeing valid JSON output;
+// "json_schema" further constrains it to JSONSchema.
 type ResponseFormat struct {
-	Type string `json:"type"` // "text" (default) or "json_object"
+	Type       string      `json:"type"` // "text" (default), "json_object" or "json_schema"
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+// JSONSchema is the schema a "json_schema" response must match
+type JSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+	Strict bool            `json:"strict,omitempty"`
+}
+
+// SchemaFormat returns a response format constraining output to schema
+func SchemaFormat(name string, schema json.RawMessage) *ResponseFormat {
+	return &ResponseFormat{Type: "json_schema", JSONSchema: &JSONSchema{Name: name, Schema: schema}}
+}
+
+// IsJSON reports whether the format asks for JSON output
+func (f *ResponseFormat) IsJSON() bool {
+	return f != nil && (f.Type == "json_object" || f.Type == "json_schema")
+}
+
+// ResponseFormatError is returned when a provider rejects the requested
+// response format, as servers without structured output support do for
+// "json_schema". Callers can retry with plain JSON mode.
+type ResponseFormatError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *ResponseFormatError) Error() string {
+	return fmt.Sprintf("response format not supported (HTTP %d): %s", e.StatusCode, e.Body)
+}
+
+// isResponseFormatError checks whether a provider error body rejects the
+// response format of a request
+func isResponseFormatError(body string) bool {
+	lower := strings.ToLower(body)
+	for _, p := range []string{"response_format", "json_schema", "guided_json", "structured output"} {
+		if strings.Contains(lower, p) {
+			return true
+		}
+	}
+	return false
 }
 
 // ChatCompletionRequest represents a chat completion request
@@ -83,6 +155,8 @@ type ChatCompletionRequest struct {
 	MaxTokens      int             `json:"max_tokens,omitempty"`
 	Stream         bool            `json:"stream,omitempty"`
 	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+	Stop           []string        `json:"stop,omitempty"`
+	Tools          []Tool          `json:"tools,omitempty"`
 }
 
 // ChatCompletionResponse represents a chat completion response
@@ -177,11 +251,7 @@ func (p *OpenAIProvider) CreateChatCompletion(ctx context.Context, req *ChatComp
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		bodyStr := string(respBody)
-		if resp.StatusCode == http.StatusBadRequest && isContextLengthError(bodyStr) {
-			return nil, &ContextLengthError{StatusCode: resp.StatusCode, Body: bodyStr}
-		}
-		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bodyStr)
+		return nil, chatCompletionError(req, resp.StatusCode, string(respBody))
 	}
 
 	// Extract and unmarshal JSON response (handling extraneous text)
@@ -193,6 +263,18 @@ func (p *OpenAIProvider) CreateChatCompletion(ctx context.Context, req *ChatComp
 	return &completionResp, nil
 }
 
+// chatCompletionError returns the error for a chat completion the provider
+// answered with a status other than 200, typed when callers can retry it
+func chatCompletionError(req *ChatCompletionRequest, statusCode int, body string) error {
+	if statusCode == http.StatusBadRequest && req.ResponseFormat != nil && req.ResponseFormat.Type == "json_schema" && isResponseFormatError(body) {
+		return &ResponseFormatError{StatusCode: statusCode, Body: body}
+	}
+	if statusCode == http.StatusBadRequest && isContextLengthError(body) {
+		return &ContextLengthError{StatusCode: statusCode, Body: body}
+	}
+	return fmt.Errorf("unexpected status code %d: %s", statusCode, body)
+}
+
 // GetModels lists available models
 func (p *OpenAIProvider) GetModels(ctx context.Context) ([]Model, error) {
 	url := fmt.Sprintf("%s/models", p.endpoint)
@@ -329,6 +329,56 @@ func TestOpenAIProvider_CreateChatCompletion_WithResponseFormat(t *testing.T) {
 	}
 }
 
+func TestOpenAIProvider_CreateChatCompletion_SchemaRefused(t *testing.T) {
+	var receivedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error": {"message": "response_format.type json_schema is not supported"}}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider(server.URL, "key")
+	_, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+		Model:          "m",
+		Messages:       []ChatMessage{{Role: "user", Content: "hi"}},
+		ResponseFormat: SchemaFormat("loom_action", json.RawMessage(`{"type":"object"}`)),
+	})
+
+	var formatErr *ResponseFormatError
+	if !errors.As(err, &formatErr) {
+		t.Fatalf("expected ResponseFormatError, got %v", err)
+	}
+	rf, _ := receivedBody["response_format"].(map[string]interface{})
+	schema, _ := rf["json_schema"].(map[string]interface{})
+	if rf["type"] != "json_schema" || schema["name"] != "loom_action" {
+		t.Errorf("response_format = %v, want the named json_schema", rf)
+	}
+}
+
+func TestOllamaProvider_CreateChatCompletion_SchemaFormat(t *testing.T) {
+	var receivedBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&receivedBody)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model": "m", "message": {"role": "assistant", "content": "{}"}, "done": true}`))
+	}))
+	defer server.Close()
+
+	p := NewOllamaProvider(server.URL)
+	_, err := p.CreateChatCompletion(context.Background(), &ChatCompletionRequest{
+		Model:          "m",
+		Messages:       []ChatMessage{{Role: "user", Content: "hi"}},
+		ResponseFormat: SchemaFormat("loom_action", json.RawMessage(`{"type":"object"}`)),
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if format, ok := receivedBody["format"].(map[string]interface{}); !ok || format["type"] != "object" {
+		t.Errorf("format = %v, want the schema itself", receivedBody["format"])
+	}
+}
+
 // ---------------------------------------------------------------------------
 // OpenAIProvider: GetModels via httptest
 // ---------------------------------------------------------------------------
@@ -0,0 +1,416 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Priority is the lane a request waits in while its provider is saturated.
+// Lower lanes are served first: a P0 request never waits behind P3 traffic.
+type Priority int
+
+const (
+	PriorityP0 Priority = iota // Interactive: a person is waiting on the answer
+	PriorityP1                 // Agent work on urgent beads
+	PriorityP2                 // Agent work and anything that sets no priority
+	PriorityP3                 // Background jobs such as benchmarks and judging
+)
+
+// priorityLanes is the number of lanes
+const priorityLanes = 4
+
+// DefaultQueueDepth is how many requests may wait for a saturated provider
+// when no depth is configured
+const DefaultQueueDepth = 100
+
+// ErrQueueFull is returned for a request shed because its provider's queue
+// is full of requests of the same or higher priority
+var ErrQueueFull = errors.New("provider request queue is full")
+
+// String returns the lane's name, p0 to p3
+func (p Priority) String() string {
+	switch p {
+	case PriorityP0:
+		return "p0"
+	case PriorityP1:
+		return "p1"
+	case PriorityP3:
+		return "p3"
+	default:
+		return "p2"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose provider requests wait in lane p
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, clampPriority(p))
+}
+
+// PriorityFromContext returns the lane requests made with ctx wait in,
+// PriorityP2 unless one was set
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityP2
+}
+
+// PriorityForBead returns the lane of agent work on a bead of the given
+// priority (0-3). Even P0 beads wait in P1, so people chatting keep the P0
+// lane to themselves.
+func PriorityForBead(beadPriority int) Priority {
+	if beadPriority < int(PriorityP1) {
+		return PriorityP1
+	}
+	return clampPriority(Priority(beadPriority))
+}
+
+func clampPriority(p Priority) Priority {
+	if p < PriorityP0 {
+		return PriorityP0
+	}
+	if p > PriorityP3 {
+		return PriorityP3
+	}
+	return p
+}
+
+// QueueConfig limits how many requests each provider serves at once.
+// Requests beyond the limit wait in priority lanes.
+type QueueConfig struct {
+	MaxConcurrent int            // Requests a provider serves at once; 0 leaves providers unlimited
+	MaxDepth      int            // Requests that may wait per provider; 0 uses DefaultQueueDepth
+	Providers     map[string]int // MaxConcurrent by provider ID
+}
+
+// limitFor returns the concurrency limit of a provider
+func (c QueueConfig) limitFor(providerID string) int {
+	if n, ok := c.Providers[providerID]; ok {
+		return n
+	}
+	return c.MaxConcurrent
+}
+
+// depth returns how many requests may wait per provider
+func (c QueueConfig) depth() int {
+	if c.MaxDepth > 0 {
+		return c.MaxDepth
+	}
+	return DefaultQueueDepth
+}
+
+// QueueStats describes a provider's request queue, per lane
+type QueueStats struct {
+	ProviderID    string             `json:"provider_id"`
+	MaxConcurrent int                `json:"max_concurrent"` // 0 means unlimited
+	MaxDepth      int                `json:"max_depth"`
+	InFlight      int                `json:"in_flight"`
+	Waiting       map[string]int     `json:"waiting"`     // Requests waiting now
+	Served        map[string]int64   `json:"served"`      // Requests let through since startup
+	Shed          map[string]int64   `json:"shed"`        // Requests rejected or evicted since startup
+	AvgWaitMs     map[string]float64 `json:"avg_wait_ms"` // Rolling average wait of requests that had to wait
+}
+
+// QueueCallback is called for every request that asks a provider for a
+// slot: once it is served, after waiting wait, or once it is shed
+type QueueCallback func(providerID string, priority Priority, wait time.Duration, shed bool)
+
+// queueWaiter is a request waiting for a slot. ready receives nil when the
+// request is handed a slot and ErrQueueFull when it is shed.
+type queueWaiter struct {
+	ready chan error
+}
+
+// requestQueue holds the requests waiting for one provider's slots.
+// Released slots go to the oldest request in the highest priority lane.
+type requestQueue struct {
+	providerID string
+
+	mu       sync.Mutex
+	limit    int
+	maxDepth int
+	inFlight int
+	lanes    [priorityLanes][]*queueWaiter
+	served   [priorityLanes]int64
+	shed     [priorityLanes]int64
+	avgWait  [priorityLanes]float64
+	callback QueueCallback
+}
+
+func newRequestQueue(providerID string, limit, maxDepth int) *requestQueue {
+	return &requestQueue{providerID: providerID, limit: limit, maxDepth: maxDepth}
+}
+
+// acquire waits for a slot in the lane of p and returns the function that
+// gives it back. When the queue is full, the newest request of the lowest
+// lane below p is shed to make room; with none, this request is shed.
+func (q *requestQueue) acquire(ctx context.Context, p Priority) (func(), error) {
+	p = clampPriority(p)
+	start := time.Now()
+
+	q.mu.Lock()
+	if q.limit <= 0 || q.inFlight < q.limit {
+		q.inFlight++
+		q.served[p]++
+		callback := q.callback
+		q.mu.Unlock()
+		if callback != nil {
+			callback(q.providerID, p, 0, false)
+		}
+		return q.releaseOnce(), nil
+	}
+	var evicted *queueWaiter
+	evictedLane := Priority(-1)
+	if q.waitingLocked() >= q.maxDepth {
+		for lane := PriorityP3; lane > p; lane-- {
+			if n := len(q.lanes[lane]); n > 0 {
+				evicted, evictedLane = q.lanes[lane][n-1], lane
+				q.lanes[lane] = q.lanes[lane][:n-1]
+				q.shed[lane]++
+				break
+			}
+		}
+		if evicted == nil {
+			q.shed[p]++
+			callback := q.callback
+			q.mu.Unlock()
+			if callback != nil {
+				callback(q.providerID, p, 0, true)
+			}
+			return nil, ErrQueueFull
+		}
+	}
+	w := &queueWaiter{ready: make(chan error, 1)}
+	q.lanes[p] = append(q.lanes[p], w)
+	callback := q.callback
+	q.mu.Unlock()
+
+	if evicted != nil {
+		evicted.ready <- ErrQueueFull
+		if callback != nil {
+			callback(q.providerID, evictedLane, 0, true)
+		}
+	}
+
+	select {
+	case err := <-w.ready:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Done():
+		q.mu.Lock()
+		if q.removeLocked(p, w) {
+			q.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		q.mu.Unlock()
+		// The request was handed a slot or shed as it gave up
+		if err := <-w.ready; err == nil {
+			q.release()
+		}
+		return nil, ctx.Err()
+	}
+
+	wait := time.Since(start)
+	q.mu.Lock()
+	q.served[p]++
+	ms := float64(wait.Milliseconds())
+	if q.avgWait[p] == 0 {
+		q.avgWait[p] = ms
+	} else {
+		q.avgWait[p] = 0.8*q.avgWait[p] + 0.2*ms
+	}
+	q.mu.Unlock()
+	if callback != nil {
+		callback(q.providerID, p, wait, false)
+	}
+	return q.releaseOnce(), nil
+}
+
+// releaseOnce returns a release function that only releases the first time
+func (q *requestQueue) releaseOnce() func() {
+	var once sync.Once
+	return func() { once.Do(q.release) }
+}
+
+// release hands a slot to the next waiting request, or frees it
+func (q *requestQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.limit > 0 && q.inFlight > q.limit {
+		// The limit was lowered: shrink rather than hand the slot on
+		q.inFlight--
+		return
+	}
+	if w := q.popLocked(); w != nil {
+		w.ready <- nil
+		return
+	}
+	q.inFlight--
+}
+
+// setLimits changes the concurrency limit and depth, letting waiting
+// requests through when the limit rose
+func (q *requestQueue) setLimits(limit, maxDepth int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.limit, q.maxDepth = limit, maxDepth
+	for q.limit <= 0 || q.inFlight < q.limit {
+		w := q.popLocked()
+		if w == nil {
+			return
+		}
+		q.inFlight++
+		w.ready <- nil
+	}
+}
+
+// popLocked removes the oldest request of the highest priority lane
+func (q *requestQueue) popLocked() *queueWaiter {
+	for lane := range q.lanes {
+		if len(q.lanes[lane]) > 0 {
+			w := q.lanes[lane][0]
+			q.lanes[lane] = q.lanes[lane][1:]
+			return w
+		}
+	}
+	return nil
+}
+
+// removeLocked takes a request that gave up out of its lane, reporting
+// whether it was still waiting
+func (q *requestQueue) removeLocked(p Priority, w *queueWaiter) bool {
+	for i, queued := range q.lanes[p] {
+		if queued == w {
+			q.lanes[p] = append(q.lanes[p][:i], q.lanes[p][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (q *requestQueue) waitingLocked() int {
+	n := 0
+	for _, lane := range q.lanes {
+		n += len(lane)
+	}
+	return n
+}
+
+// stats returns the queue's current state
+func (q *requestQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	s := QueueStats{
+		ProviderID:    q.providerID,
+		MaxConcurrent: q.limit,
+		MaxDepth:      q.maxDepth,
+		InFlight:      q.inFlight,
+		Waiting:       make(map[string]int, priorityLanes),
+		Served:        make(map[string]int64, priorityLanes),
+		Shed:          make(map[string]int64, priorityLanes),
+		AvgWaitMs:     make(map[string]float64, priorityLanes),
+	}
+	for lane := PriorityP0; lane <= PriorityP3; lane++ {
+		s.Waiting[lane.String()] = len(q.lanes[lane])
+		s.Served[lane.String()] = q.served[lane]
+		s.Shed[lane.String()] = q.shed[lane]
+		s.AvgWaitMs[lane.String()] = q.avgWait[lane]
+	}
+	return s
+}
+
+// Acquire waits for a free slot on the provider, in the lane of the
+// context's priority, and returns the function that frees it again.
+// Providers registered outside a registry are never queued.
+func (p *RegisteredProvider) Acquire(ctx context.Context) (func(), error) {
+	if p.queue == nil {
+		return func() {}, nil
+	}
+	return p.queue.acquire(ctx, PriorityFromContext(ctx))
+}
+
+// CreateChatCompletion sends a request once the provider has a free slot
+func (p *RegisteredProvider) CreateChatCompletion(ctx context.Context, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
+	release, err := p.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return p.Protocol.CreateChatCompletion(ctx, req)
+}
+
+// GetModels lists the provider's models without queueing
+func (p *RegisteredProvider) GetModels(ctx context.Context) ([]Model, error) {
+	return p.Protocol.GetModels(ctx)
+}
+
+// SetQueueConfig sets how many requests each provider serves at once and
+// how many may wait, for registered providers and those registered later
+func (r *Registry) SetQueueConfig(cfg QueueConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queueConfig = cfg
+	for id, q := range r.queues {
+		q.setLimits(cfg.limitFor(id), cfg.depth())
+	}
+}
+
+// SetQueueCallback sets the function told about every request that leaves
+// a provider's queue
+func (r *Registry) SetQueueCallback(callback QueueCallback) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queueCallback = callback
+	for _, q := range r.queues {
+		q.mu.Lock()
+		q.callback = callback
+		q.mu.Unlock()
+	}
+}
+
+// QueueStats returns the request queue of every registered provider
+func (r *Registry) QueueStats() []QueueStats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := make([]QueueStats, 0, len(r.providers))
+	for id := range r.providers {
+		if q, ok := r.queues[id]; ok {
+			list = append(list, q.stats())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ProviderID < list[j].ProviderID })
+	return list
+}
+
+// ProviderQueueStats returns a registered provider's request queue
+func (r *Registry) ProviderQueueStats(providerID string) (QueueStats, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if _, ok := r.providers[providerID]; !ok {
+		return QueueStats{}, false
+	}
+	q, ok := r.queues[providerID]
+	if !ok {
+		return QueueStats{}, false
+	}
+	return q.stats(), true
+}
+
+// queueLocked returns a provider's request queue, creating it the first
+// time. The queue outlives re-registrations so requests in flight keep
+// their slots. The caller holds r.mu.
+func (r *Registry) queueLocked(providerID string) *requestQueue {
+	if q, ok := r.queues[providerID]; ok {
+		return q
+	}
+	q := newRequestQueue(providerID, r.queueConfig.limitFor(providerID), r.queueConfig.depth())
+	q.callback = r.queueCallback
+	r.queues[providerID] = q
+	return q
+}
@@ -0,0 +1,206 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitForWaiting polls until the queue holds n waiting requests
+func waitForWaiting(t *testing.T, q *requestQueue, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		q.mu.Lock()
+		waiting := q.waitingLocked()
+		q.mu.Unlock()
+		if waiting == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("expected %d waiting requests", n)
+}
+
+func TestRequestQueuePriorityOrder(t *testing.T) {
+	q := newRequestQueue("p", 1, 10)
+	release, err := q.acquire(context.Background(), PriorityP2)
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	order := make(chan Priority, 3)
+	for i, p := range []Priority{PriorityP3, PriorityP2, PriorityP0} {
+		go func(p Priority) {
+			rel, err := q.acquire(context.Background(), p)
+			if err != nil {
+				t.Errorf("acquire %s: %v", p, err)
+				return
+			}
+			order <- p
+			rel()
+		}(p)
+		waitForWaiting(t, q, i+1)
+	}
+
+	release()
+	for _, want := range []Priority{PriorityP0, PriorityP2, PriorityP3} {
+		if got := <-order; got != want {
+			t.Errorf("expected %s served next, got %s", want, got)
+		}
+	}
+	stats := q.stats()
+	if stats.InFlight != 0 || stats.Served["p2"] != 2 || stats.Served["p0"] != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRequestQueueSheds(t *testing.T) {
+	q := newRequestQueue("p", 1, 1)
+	var shed []Priority
+	q.callback = func(_ string, p Priority, _ time.Duration, wasShed bool) {
+		if wasShed {
+			shed = append(shed, p)
+		}
+	}
+	release, _ := q.acquire(context.Background(), PriorityP2)
+
+	evicted := make(chan error, 1)
+	go func() {
+		_, err := q.acquire(context.Background(), PriorityP3)
+		evicted <- err
+	}()
+	waitForWaiting(t, q, 1)
+
+	// The queue is full: a request of the same priority is shed
+	if _, err := q.acquire(context.Background(), PriorityP3); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+
+	// A higher priority request takes the waiting P3 request's place
+	served := make(chan struct{})
+	go func() {
+		rel, err := q.acquire(context.Background(), PriorityP0)
+		if err != nil {
+			t.Errorf("acquire p0: %v", err)
+			return
+		}
+		rel()
+		close(served)
+	}()
+	if err := <-evicted; !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected waiting p3 request to be shed, got %v", err)
+	}
+	release()
+	<-served
+
+	if len(shed) != 2 || shed[0] != PriorityP3 || shed[1] != PriorityP3 {
+		t.Errorf("expected two p3 requests shed, got %v", shed)
+	}
+	if s := q.stats(); s.Shed["p3"] != 2 || s.InFlight != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+func TestRequestQueueCancel(t *testing.T) {
+	q := newRequestQueue("p", 1, 10)
+	release, _ := q.acquire(context.Background(), PriorityP2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.acquire(ctx, PriorityP2)
+		done <- err
+	}()
+	waitForWaiting(t, q, 1)
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+
+	release()
+	if s := q.stats(); s.InFlight != 0 || s.Waiting["p2"] != 0 {
+		t.Errorf("expected an empty queue, got %+v", s)
+	}
+}
+
+func TestRequestQueueSetLimits(t *testing.T) {
+	q := newRequestQueue("p", 1, 10)
+	release, _ := q.acquire(context.Background(), PriorityP2)
+
+	done := make(chan func(), 1)
+	go func() {
+		rel, err := q.acquire(context.Background(), PriorityP2)
+		if err != nil {
+			t.Errorf("acquire: %v", err)
+		}
+		done <- rel
+	}()
+	waitForWaiting(t, q, 1)
+
+	// Raising the limit lets the waiting request through
+	q.setLimits(2, 10)
+	second := <-done
+	if s := q.stats(); s.InFlight != 2 {
+		t.Fatalf("expected 2 in flight, got %d", s.InFlight)
+	}
+
+	// Lowering it shrinks the slots as they are given back
+	q.setLimits(1, 10)
+	release()
+	if s := q.stats(); s.InFlight != 1 {
+		t.Fatalf("expected 1 in flight, got %d", s.InFlight)
+	}
+	second()
+	if s := q.stats(); s.InFlight != 0 {
+		t.Fatalf("expected 0 in flight, got %d", s.InFlight)
+	}
+}
+
+func TestRegistryQueue(t *testing.T) {
+	r := NewRegistry()
+	r.SetQueueConfig(QueueConfig{MaxConcurrent: 4, Providers: map[string]int{"small": 1}})
+	if err := r.Register(&ProviderConfig{ID: "small", Type: "mock", Status: "active"}); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	stats, ok := r.ProviderQueueStats("small")
+	if !ok || stats.MaxConcurrent != 1 || stats.MaxDepth != DefaultQueueDepth {
+		t.Fatalf("unexpected stats: %+v, %v", stats, ok)
+	}
+
+	p, _ := r.Get("small")
+	release, err := p.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(WithPriority(context.Background(), PriorityP0), 20*time.Millisecond)
+	defer cancel()
+	if _, err := r.SendChatCompletion(ctx, "small", &ChatCompletionRequest{Model: "m"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the request to time out waiting, got %v", err)
+	}
+	release()
+
+	// Re-registering keeps the queue and its counts
+	if err := r.Upsert(&ProviderConfig{ID: "small", Type: "mock", Status: "active"}); err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if stats, _ := r.ProviderQueueStats("small"); stats.Served["p2"] != 1 {
+		t.Errorf("expected the queue to survive re-registration, got %+v", stats)
+	}
+	if _, ok := r.ProviderQueueStats("missing"); ok {
+		t.Error("expected no queue for an unregistered provider")
+	}
+}
+
+func TestPriorityForBead(t *testing.T) {
+	cases := map[int]Priority{0: PriorityP1, 1: PriorityP1, 2: PriorityP2, 3: PriorityP3, 7: PriorityP3}
+	for bead, want := range cases {
+		if got := PriorityForBead(bead); got != want {
+			t.Errorf("PriorityForBead(%d) = %s, want %s", bead, got, want)
+		}
+	}
+	if got := PriorityFromContext(context.Background()); got != PriorityP2 {
+		t.Errorf("expected p2 without a priority, got %s", got)
+	}
+}
@@ -3,13 +3,21 @@ package provider
 import (
 	"context"
 	"fmt"
-	"log"
 	"sort"
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/jordanhubbard/loom/internal/logging"
 )
 
+// providerLog is the structured logger of provider routing and requests
+var providerLog = logging.Logger("provider")
+
+// DemoTag marks the demo mode's scripted mock provider. Dispatch routes only
+// projects whose compliance constraints require the tag to it.
+const DemoTag = "demo"
+
 // ProviderConfig represents the configuration for a provider
 type ProviderConfig struct {
 	ID                     string    `json:"id"`
@@ -26,13 +34,15 @@ type ProviderConfig struct {
 	LastHeartbeatLatencyMs int64     `json:"last_heartbeat_latency_ms,omitempty"`
 	CapabilityScore        float64   `json:"capability_score,omitempty"` // Dynamic composite score from Scorer
 	ContextWindow          int       `json:"context_window,omitempty"`
+	Tags                   []string  `json:"tags,omitempty"`   // e.g. "eu-hosted"; matched against project compliance constraints
+	OrgID                  string    `json:"org_id,omitempty"` // Organization whose projects may use it; empty shares it with every organization
 
 	// Model metadata for scoring
-	ModelParamsB    float64 `json:"model_params_b,omitempty"`     // Total model parameters in billions
-	CostPerMToken   float64 `json:"cost_per_mtoken,omitempty"`    // Cost per million tokens ($)
-	AvgLatencyMs    float64 `json:"avg_latency_ms,omitempty"`     // Rolling average request latency
-	TotalRequests   int64   `json:"total_requests,omitempty"`     // Total requests served
-	SuccessRequests int64   `json:"success_requests,omitempty"`   // Successful requests
+	ModelParamsB    float64 `json:"model_params_b,omitempty"`   // Total model parameters in billions
+	CostPerMToken   float64 `json:"cost_per_mtoken,omitempty"`  // Cost per million tokens ($)
+	AvgLatencyMs    float64 `json:"avg_latency_ms,omitempty"`   // Rolling average request latency
+	TotalRequests   int64   `json:"total_requests,omitempty"`   // Total requests served
+	SuccessRequests int64   `json:"success_requests,omitempty"` // Successful requests
 }
 
 // MetricsCallback is called after each provider request to record metrics
@@ -45,12 +55,21 @@ type Registry struct {
 	metricsCallback MetricsCallback
 	rrCounter       uint64  // Round-robin counter for equal-priority providers
 	scorer          *Scorer // Dynamic provider scoring
+	metadata        ModelMetadataSource
+	pricer          Pricer
+	queueConfig     QueueConfig
+	queueCallback   QueueCallback
+	queues          map[string]*requestQueue // providerID -> requests waiting for a slot
+	saturation      SaturationConfig
+	loads           map[string]ServerLoad // providerID -> load its inference server last reported
 }
 
 // RegisteredProvider wraps a provider with its configuration and protocol
 type RegisteredProvider struct {
 	Config   *ProviderConfig
 	Protocol Protocol
+
+	queue *requestQueue // Nil for providers registered outside a registry
 }
 
 // NewRegistry creates a new provider registry
@@ -58,6 +77,8 @@ func NewRegistry() *Registry {
 	return &Registry{
 		providers: make(map[string]*RegisteredProvider),
 		scorer:    NewScorer(),
+		queues:    make(map[string]*requestQueue),
+		loads:     make(map[string]ServerLoad),
 	}
 }
 
@@ -82,28 +103,69 @@ func (r *Registry) Register(config *ProviderConfig) error {
 	}
 
 	// Create protocol based on provider type
-	var protocol Protocol
-	switch config.Type {
-	case "openai", "anthropic", "local", "custom", "vllm":
-		// All use OpenAI-compatible protocol
-		protocol = NewOpenAIProvider(config.Endpoint, config.APIKey)
-	case "ollama":
-		protocol = NewOllamaProvider(config.Endpoint)
-	case "mock":
-		protocol = NewMockProvider()
-	default:
-		return fmt.Errorf("unsupported provider type: %s", config.Type)
+	protocol, err := NewProtocol(config)
+	if err != nil {
+		return err
 	}
 
 	// Register provider
 	r.providers[config.ID] = &RegisteredProvider{
 		Config:   config,
 		Protocol: protocol,
+		queue:    r.queueLocked(config.ID),
 	}
 
 	return nil
 }
 
+// Cloud provider types, reached only through their own protocol at the
+// endpoint as configured
+const (
+	TypeAzureOpenAI = "azure"
+	TypeBedrock     = "bedrock"
+	TypeGemini      = "gemini"
+)
+
+// IsCloudType reports whether providers of a type are cloud services with
+// their own protocol, whose endpoints are neither probed nor rewritten
+func IsCloudType(providerType string) bool {
+	switch providerType {
+	case TypeAzureOpenAI, TypeBedrock, TypeGemini:
+		return true
+	default:
+		return false
+	}
+}
+
+// HasNativeEndpoint reports whether a provider type's endpoint is used as
+// configured rather than as an OpenAI-compatible base URL ending in /v1
+func HasNativeEndpoint(providerType string) bool {
+	return providerType == "ollama" || IsCloudType(providerType)
+}
+
+// NewProtocol creates the protocol implementation for a provider config
+func NewProtocol(config *ProviderConfig) (Protocol, error) {
+	switch config.Type {
+	case "openai", "anthropic", "local", "custom":
+		// All use OpenAI-compatible protocol
+		return NewOpenAIProvider(config.Endpoint, config.APIKey), nil
+	case TypeVLLM, TypeTGI:
+		return NewSelfHostedProvider(config.Type, config.Endpoint, config.APIKey), nil
+	case "ollama":
+		return NewOllamaProvider(config.Endpoint), nil
+	case TypeAzureOpenAI:
+		return NewAzureOpenAIProvider(config.Endpoint, config.APIKey, config.Model)
+	case TypeBedrock:
+		return NewBedrockProvider(config.Endpoint, config.APIKey, config.Model)
+	case TypeGemini:
+		return NewGeminiProvider(config.Endpoint, config.APIKey, config.Model)
+	case "mock":
+		return NewMockProvider(), nil
+	default:
+		return nil, fmt.Errorf("unsupported provider type: %s", config.Type)
+	}
+}
+
 // Upsert registers a provider if it doesn't exist, or replaces it if it does.
 func (r *Registry) Upsert(config *ProviderConfig) error {
 	r.mu.Lock()
@@ -112,22 +174,26 @@ func (r *Registry) Upsert(config *ProviderConfig) error {
 		config.Status = "pending"
 	}
 
-	var protocol Protocol
-	switch config.Type {
-	case "openai", "anthropic", "local", "custom", "vllm":
-		protocol = NewOpenAIProvider(config.Endpoint, config.APIKey)
-	case "ollama":
-		protocol = NewOllamaProvider(config.Endpoint)
-	case "mock":
-		protocol = NewMockProvider()
-	default:
-		return fmt.Errorf("unsupported provider type: %s", config.Type)
+	protocol, err := NewProtocol(config)
+	if err != nil {
+		return err
 	}
 
-	r.providers[config.ID] = &RegisteredProvider{Config: config, Protocol: protocol}
+	r.providers[config.ID] = &RegisteredProvider{Config: config, Protocol: protocol, queue: r.queueLocked(config.ID)}
 	return nil
 }
 
+// UpsertProtocol registers a provider whose protocol is built in-process,
+// such as a scripted mock, replacing any provider with the same ID.
+func (r *Registry) UpsertProtocol(config *ProviderConfig, protocol Protocol) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if config.Status == "" {
+		config.Status = "pending"
+	}
+	r.providers[config.ID] = &RegisteredProvider{Config: config, Protocol: protocol, queue: r.queueLocked(config.ID)}
+}
+
 // Unregister removes a provider from the registry
 func (r *Registry) Unregister(providerID string) error {
 	r.mu.Lock()
@@ -138,6 +204,8 @@ func (r *Registry) Unregister(providerID string) error {
 	}
 
 	delete(r.providers, providerID)
+	delete(r.queues, providerID)
+	delete(r.loads, providerID)
 	return nil
 }
 
@@ -256,8 +324,6 @@ func (r *Registry) SetMetricsCallback(callback MetricsCallback) {
 
 // SendChatCompletionStream sends a streaming chat completion request to a provider
 func (r *Registry) SendChatCompletionStream(ctx context.Context, providerID string, req *ChatCompletionRequest, handler StreamHandler) error {
-	start := time.Now()
-
 	// Get provider
 	registered, err := r.Get(providerID)
 	if err != nil {
@@ -270,6 +336,19 @@ func (r *Registry) SendChatCompletionStream(ctx context.Context, providerID stri
 		return fmt.Errorf("provider %s does not support streaming", providerID)
 	}
 
+	// Wait for a free slot while the provider is saturated
+	release, err := registered.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	start := time.Now()
+
+	if req.Model == "" && registered.Config != nil {
+		req.Model = registered.Config.Model
+	}
+	r.shapeRequest(ctx, providerID, req)
+
 	// Send streaming request
 	err = streamProvider.CreateChatCompletionStream(ctx, req, handler)
 
@@ -279,13 +358,18 @@ func (r *Registry) SendChatCompletionStream(ctx context.Context, providerID stri
 		r.metricsCallback(providerID, err == nil, latencyMs, 0)
 	}
 
+	if err != nil {
+		providerLog.WarnContext(ctx, "streaming chat completion failed",
+			"provider_id", providerID, "model", req.Model, "latency_ms", latencyMs, "error", err)
+	} else {
+		providerLog.DebugContext(ctx, "streaming chat completion",
+			"provider_id", providerID, "model", req.Model, "latency_ms", latencyMs)
+	}
 	return err
 }
 
 // SendChatCompletion sends a chat completion request to a provider
 func (r *Registry) SendChatCompletion(ctx context.Context, providerID string, req *ChatCompletionRequest) (*ChatCompletionResponse, error) {
-	startTime := time.Now()
-
 	provider, err := r.Get(providerID)
 	if err != nil {
 		return nil, err
@@ -294,10 +378,20 @@ func (r *Registry) SendChatCompletion(ctx context.Context, providerID string, re
 		return nil, fmt.Errorf("provider %s is disabled", providerID)
 	}
 
+	// Wait for a free slot while the provider is saturated; the wait does
+	// not count toward the provider's latency
+	release, err := provider.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	startTime := time.Now()
+
 	// Use default model if not specified
 	if req.Model == "" {
 		req.Model = provider.Config.Model
 	}
+	r.shapeRequest(ctx, providerID, req)
 
 	// Make the request
 	resp, err := provider.Protocol.CreateChatCompletion(ctx, req)
@@ -305,11 +399,11 @@ func (r *Registry) SendChatCompletion(ctx context.Context, providerID string, re
 	// If model not found (404), the vLLM server may have restarted with a
 	// different model. Rediscover available models and retry once.
 	if err != nil && (strings.Contains(err.Error(), "status code 404") || strings.Contains(err.Error(), "not found")) {
-		log.Printf("[Registry] Model %q returned 404 on provider %s — rediscovering models", req.Model, providerID)
+		providerLog.WarnContext(ctx, "model not found on provider, rediscovering models", "provider_id", providerID, "model", req.Model)
 		models, modelErr := provider.Protocol.GetModels(ctx)
 		if modelErr == nil && len(models) > 0 {
 			newModel := models[0].ID
-			log.Printf("[Registry] Provider %s model changed: %q → %q", providerID, req.Model, newModel)
+			providerLog.InfoContext(ctx, "provider model changed", "provider_id", providerID, "model", req.Model, "new_model", newModel)
 			r.mu.Lock()
 			if p, ok := r.providers[providerID]; ok && p.Config != nil {
 				p.Config.Model = newModel
@@ -341,6 +435,13 @@ func (r *Registry) SendChatCompletion(ctx context.Context, providerID string, re
 		callback(providerID, success, latencyMs, totalTokens)
 	}
 
+	if err != nil {
+		providerLog.WarnContext(ctx, "chat completion failed",
+			"provider_id", providerID, "model", req.Model, "latency_ms", latencyMs, "error", err)
+	} else {
+		providerLog.DebugContext(ctx, "chat completion",
+			"provider_id", providerID, "model", req.Model, "latency_ms", latencyMs, "tokens", totalTokens)
+	}
 	return resp, err
 }
 
@@ -370,6 +471,11 @@ func (r *Registry) UpdateProviderScore(providerID string, modelParamsB float64,
 		return
 	}
 
+	// Models without a parameter count in their name rank by declared tier
+	if modelParamsB == 0 {
+		modelParamsB = r.declaredParamsB(providerID)
+	}
+
 	cfg := provider.Config
 	cfg.ModelParamsB = modelParamsB
 	cfg.CostPerMToken = costPerMToken
@@ -412,6 +518,7 @@ func (r *Registry) RecordRequestMetrics(providerID string, latencyMs int64, succ
 
 	// Update the scorer with new metrics
 	if r.scorer != nil {
+		r.scorer.RecordRequest(providerID, success, time.Now())
 		score := r.scorer.UpdateProviderMetrics(
 			providerID,
 			cfg.ModelParamsB,
@@ -456,6 +563,64 @@ func (r *Registry) UpdateHeartbeatLatency(providerID string, latencyMs int64) {
 	}
 }
 
+// UpdateProviderQuality records the mean judge grade (0-1) of a provider's
+// work and recalculates its score.
+func (r *Registry) UpdateProviderQuality(providerID string, quality float64, samples int) {
+	if r.scorer == nil {
+		return
+	}
+	score := r.scorer.SetProviderQuality(providerID, quality, samples)
+	if score == nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if provider, ok := r.providers[providerID]; ok && provider != nil && provider.Config != nil {
+		provider.Config.CapabilityScore = score.CompositeScore
+	}
+}
+
+// RecordBenchmark folds a benchmark's measured request latency and cost
+// per million tokens into a provider's metrics and recalculates its score.
+// The latency is averaged with what live traffic measured; a cost of 0
+// leaves the known cost alone.
+func (r *Registry) RecordBenchmark(providerID string, avgLatencyMs, costPerMToken float64) {
+	r.mu.Lock()
+	provider, exists := r.providers[providerID]
+	if !exists || provider == nil || provider.Config == nil {
+		r.mu.Unlock()
+		return
+	}
+
+	cfg := provider.Config
+	if avgLatencyMs > 0 {
+		if cfg.AvgLatencyMs == 0 {
+			cfg.AvgLatencyMs = avgLatencyMs
+		} else {
+			cfg.AvgLatencyMs = 0.5*cfg.AvgLatencyMs + 0.5*avgLatencyMs
+		}
+	}
+	if costPerMToken > 0 {
+		cfg.CostPerMToken = costPerMToken
+	}
+	r.mu.Unlock()
+
+	if r.scorer != nil {
+		score := r.scorer.UpdateProviderMetrics(
+			providerID,
+			cfg.ModelParamsB,
+			cfg.LastHeartbeatLatencyMs,
+			cfg.AvgLatencyMs,
+			cfg.CostPerMToken,
+		)
+
+		r.mu.Lock()
+		cfg.CapabilityScore = score.CompositeScore
+		r.mu.Unlock()
+	}
+}
+
 // SetScoringWeights updates the scoring weights used for provider prioritization.
 func (r *Registry) SetScoringWeights(weights ScoringWeights) {
 	if r.scorer != nil {
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // ---------------------------------------------------------------------------
@@ -480,6 +482,74 @@ func TestRegistryRecordRequestMetrics_NonExistent(t *testing.T) {
 	r.RecordRequestMetrics("nope", 100, true)
 }
 
+// ---------------------------------------------------------------------------
+// Registry: RecordBenchmark
+// ---------------------------------------------------------------------------
+
+func TestRegistryRecordBenchmark(t *testing.T) {
+	r := NewRegistry()
+	_ = r.Upsert(&ProviderConfig{
+		ID: "bm", Type: "mock", Model: "m", Status: "healthy", CostPerMToken: 2,
+	})
+
+	r.RecordBenchmark("bm", 1000, 0)
+	p, _ := r.Get("bm")
+	if p.Config.AvgLatencyMs != 1000 || p.Config.CostPerMToken != 2 {
+		t.Errorf("first benchmark: latency %f, cost %f", p.Config.AvgLatencyMs, p.Config.CostPerMToken)
+	}
+	if p.Config.CapabilityScore == 0 {
+		t.Error("expected the benchmark to score the provider")
+	}
+
+	r.RecordBenchmark("bm", 500, 3)
+	p, _ = r.Get("bm")
+	if p.Config.AvgLatencyMs != 750 || p.Config.CostPerMToken != 3 {
+		t.Errorf("second benchmark: latency %f, cost %f", p.Config.AvgLatencyMs, p.Config.CostPerMToken)
+	}
+
+	// Should not panic
+	r.RecordBenchmark("nope", 100, 1)
+}
+
+func TestRegistryScoreMetricsRoundTrip(t *testing.T) {
+	before := NewRegistry()
+	_ = before.Upsert(&ProviderConfig{ID: "p", Type: "mock", Model: "m", Status: "healthy", ModelParamsB: 70})
+	_ = before.Upsert(&ProviderConfig{ID: "unscored", Type: "mock", Model: "m", Status: "healthy"})
+	for i := 0; i < 4; i++ {
+		before.RecordRequestMetrics("p", 400, i != 0)
+	}
+	now := time.Now()
+	saved := before.ScoreMetrics(now)
+	if len(saved) != 1 || saved[0].ProviderID != "p" || math.Abs(saved[0].ErrorRate-0.25) > 0.001 || saved[0].AvgRequestLatencyMs != 400 {
+		t.Fatalf("ScoreMetrics = %+v", saved)
+	}
+
+	// A restart: the provider is registered again without its metrics
+	after := NewRegistry()
+	_ = after.Upsert(&ProviderConfig{ID: "p", Type: "mock", Model: "m", Status: "healthy"})
+	if n := after.RestoreScoreMetrics(append(saved, ScoreMetrics{ProviderID: "gone"})); n != 1 {
+		t.Fatalf("restored %d providers, want 1", n)
+	}
+	p, _ := after.Get("p")
+	if p.Config.ModelParamsB != 70 || p.Config.AvgLatencyMs != 400 || p.Config.CapabilityScore == 0 {
+		t.Errorf("restored config = %+v", p.Config)
+	}
+	score, ok := after.GetScorer().GetScore("p")
+	if !ok || math.Abs(score.ErrorRate-0.25) > 0.001 || math.Abs(score.CompositeScore-saved[0].CompositeScore) > 0.01 {
+		t.Errorf("restored score = %+v, saved composite %f", score, saved[0].CompositeScore)
+	}
+
+	// Metrics saved long ago keep decaying from when they were recorded
+	stale := saved[0]
+	stale.RecordedAt = now.Add(-10 * DefaultMetricsHalfLife)
+	old := NewRegistry()
+	_ = old.Upsert(&ProviderConfig{ID: "p", Type: "mock", Model: "m", Status: "healthy"})
+	old.RestoreScoreMetrics([]ScoreMetrics{stale})
+	if score, _ := old.GetScorer().GetScore("p"); score.Requests > 0.01 || score.ReliabilityScore != 100 {
+		t.Errorf("expected stale request counts to decay, got %+v", score)
+	}
+}
+
 // ---------------------------------------------------------------------------
 // Registry: UpdateHeartbeatLatency
 // ---------------------------------------------------------------------------
@@ -864,8 +934,8 @@ func TestRegistryRegister_VLLMType(t *testing.T) {
 		t.Fatalf("Register vllm: %v", err)
 	}
 	p, _ := r.Get("vllm1")
-	if _, ok := p.Protocol.(*OpenAIProvider); !ok {
-		t.Error("expected OpenAIProvider protocol for vllm type")
+	if _, ok := p.Protocol.(*SelfHostedProvider); !ok {
+		t.Error("expected SelfHostedProvider protocol for vllm type")
 	}
 }
 
@@ -0,0 +1,119 @@
+package provider
+
+import "time"
+
+// ScoreMetrics is what the scorer has measured about a provider, in the
+// form it is persisted in so that ranking survives a restart
+type ScoreMetrics struct {
+	ProviderID          string    `json:"provider_id"`
+	ModelParamsB        float64   `json:"model_params_b"`
+	HeartbeatLatencyMs  int64     `json:"heartbeat_latency_ms"`
+	AvgRequestLatencyMs float64   `json:"avg_request_latency_ms"`
+	CostPerMToken       float64   `json:"cost_per_mtoken"`
+	Requests            float64   `json:"requests"` // Recent requests, decayed by age
+	Failures            float64   `json:"failures"` // Recent failed requests, decayed by age
+	ErrorRate           float64   `json:"error_rate"`
+	CompositeScore      float64   `json:"composite_score"`
+	RecordedAt          time.Time `json:"recorded_at"` // When the request counts were decayed to
+}
+
+// outcomesAt returns a provider's request counts decayed to now
+func (s *Scorer) outcomesAt(providerID string, now time.Time) requestOutcomes {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.outcomes[providerID].decayed(now, s.halfLife)
+}
+
+// restoreOutcomes replaces a provider's request counts with persisted ones
+// that were current at at; they keep decaying from then
+func (s *Scorer) restoreOutcomes(providerID string, requests, failures float64, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outcomes[providerID] = requestOutcomes{requests: requests, failures: failures, at: at}
+}
+
+// ScoreMetrics returns the metrics of every registered provider the scorer
+// has scored, with request counts decayed to now
+func (r *Registry) ScoreMetrics(now time.Time) []ScoreMetrics {
+	if r.scorer == nil {
+		return nil
+	}
+	var list []ScoreMetrics
+	for _, p := range r.List() {
+		if p == nil || p.Config == nil {
+			continue
+		}
+		score, ok := r.scorer.GetScore(p.Config.ID)
+		if !ok {
+			continue
+		}
+		o := r.scorer.outcomesAt(p.Config.ID, now)
+		r.mu.RLock()
+		m := ScoreMetrics{
+			ProviderID:          p.Config.ID,
+			ModelParamsB:        p.Config.ModelParamsB,
+			HeartbeatLatencyMs:  p.Config.LastHeartbeatLatencyMs,
+			AvgRequestLatencyMs: p.Config.AvgLatencyMs,
+			CostPerMToken:       p.Config.CostPerMToken,
+			Requests:            o.requests,
+			Failures:            o.failures,
+			ErrorRate:           o.errorRate(),
+			CompositeScore:      score.CompositeScore,
+			RecordedAt:          now,
+		}
+		r.mu.RUnlock()
+		list = append(list, m)
+	}
+	return list
+}
+
+// RestoreScoreMetrics loads persisted metrics into the registered providers
+// and rescores them, returning how many were restored. Metrics measured
+// since startup win over persisted ones, and persisted request counts keep
+// decaying from when they were recorded, so a provider that failed long ago
+// is not held to it.
+func (r *Registry) RestoreScoreMetrics(metrics []ScoreMetrics) int {
+	if r.scorer == nil {
+		return 0
+	}
+	restored := 0
+	for _, m := range metrics {
+		r.mu.Lock()
+		provider, exists := r.providers[m.ProviderID]
+		if !exists || provider == nil || provider.Config == nil {
+			r.mu.Unlock()
+			continue
+		}
+		cfg := provider.Config
+		if cfg.ModelParamsB == 0 {
+			cfg.ModelParamsB = m.ModelParamsB
+		}
+		if cfg.LastHeartbeatLatencyMs == 0 {
+			cfg.LastHeartbeatLatencyMs = m.HeartbeatLatencyMs
+		}
+		if cfg.AvgLatencyMs == 0 {
+			cfg.AvgLatencyMs = m.AvgRequestLatencyMs
+		}
+		if cfg.CostPerMToken == 0 {
+			cfg.CostPerMToken = m.CostPerMToken
+		}
+		r.mu.Unlock()
+
+		if o := r.scorer.outcomesAt(m.ProviderID, m.RecordedAt); o.requests == 0 {
+			r.scorer.restoreOutcomes(m.ProviderID, m.Requests, m.Failures, m.RecordedAt)
+		}
+		score := r.scorer.UpdateProviderMetrics(
+			m.ProviderID,
+			cfg.ModelParamsB,
+			cfg.LastHeartbeatLatencyMs,
+			cfg.AvgLatencyMs,
+			cfg.CostPerMToken,
+		)
+
+		r.mu.Lock()
+		cfg.CapabilityScore = score.CompositeScore
+		r.mu.Unlock()
+		restored++
+	}
+	return restored
+}
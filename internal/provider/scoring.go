@@ -10,6 +10,8 @@ import (
 // Higher weight = more important. Weights are used for tie-breaking in priority order.
 type ScoringWeights struct {
 	ModelSize    float64 `json:"model_size"`    // Weight 1: Larger models are better (highest priority)
+	Quality      float64 `json:"quality"`       // Judges' grades of the provider's work
+	Reliability  float64 `json:"reliability"`   // Share of recent requests that succeeded
 	RoundTrip    float64 `json:"round_trip"`    // Weight 2: Heartbeat/connectivity latency
 	RequestLatency float64 `json:"request_latency"` // Weight 3: Per-request response time
 	Cost         float64 `json:"cost"`          // Weight 4: $/token cost (lowest priority, placeholder)
@@ -17,10 +19,12 @@ type ScoringWeights struct {
 
 // DefaultWeights returns the default scoring weights.
 // The weights are set so that factors are evaluated in priority order:
-// model size > round trip > request latency > cost
+// model size > quality > reliability > round trip > request latency > cost
 func DefaultWeights() ScoringWeights {
 	return ScoringWeights{
 		ModelSize:      1000.0, // Dominates all other factors
+		Quality:        500.0,  // Graded work outweighs speed
+		Reliability:    200.0,  // Failing requests cost more than slow ones
 		RoundTrip:      100.0,  // Secondary factor
 		RequestLatency: 10.0,   // Tertiary factor
 		Cost:           1.0,    // Tie-breaker (currently $0 for all)
@@ -33,6 +37,8 @@ type ProviderScore struct {
 
 	// Component scores (0-100 scale, higher is better)
 	ModelSizeScore      float64 `json:"model_size_score"`
+	QualityScore        float64 `json:"quality_score"`
+	ReliabilityScore    float64 `json:"reliability_score"`
 	RoundTripScore      float64 `json:"round_trip_score"`
 	RequestLatencyScore float64 `json:"request_latency_score"`
 	CostScore           float64 `json:"cost_score"`
@@ -45,6 +51,10 @@ type ProviderScore struct {
 	HeartbeatLatencyMs int64   `json:"heartbeat_latency_ms"`  // Last heartbeat round-trip time
 	AvgRequestLatencyMs float64 `json:"avg_request_latency_ms"` // Rolling average request latency
 	CostPerMToken      float64 `json:"cost_per_mtoken"`       // Cost per million tokens
+	Quality            float64 `json:"quality"`               // Mean judge grade, 0-1; 0 until graded
+	QualitySamples     int     `json:"quality_samples"`       // Evaluations behind Quality
+	ErrorRate          float64 `json:"error_rate"`            // Share of recent requests that failed, 0-1
+	Requests           float64 `json:"requests"`              // Recent requests behind ErrorRate, decayed by age
 
 	LastUpdated time.Time `json:"last_updated"`
 }
@@ -54,6 +64,11 @@ type Scorer struct {
 	mu      sync.RWMutex
 	weights ScoringWeights
 	scores  map[string]*ProviderScore // providerID -> score
+	quality map[string]providerQuality // providerID -> judged quality
+	outcomes map[string]requestOutcomes // providerID -> decayed request counts
+
+	// halfLife is how long it takes a request outcome to count half as much
+	halfLife time.Duration
 
 	// Normalization bounds (learned from observed data)
 	maxModelParams       float64
@@ -67,6 +82,9 @@ func NewScorer() *Scorer {
 	return &Scorer{
 		weights:              DefaultWeights(),
 		scores:               make(map[string]*ProviderScore),
+		quality:              make(map[string]providerQuality),
+		outcomes:             make(map[string]requestOutcomes),
+		halfLife:             DefaultMetricsHalfLife,
 		maxModelParams:       500.0,  // 500B params as baseline max
 		maxHeartbeatLatency:  5000.0, // 5 seconds as baseline max
 		maxRequestLatency:    30000.0, // 30 seconds as baseline max
@@ -74,6 +92,45 @@ func NewScorer() *Scorer {
 	}
 }
 
+// providerQuality is the mean judge grade of a provider's work
+type providerQuality struct {
+	quality float64
+	samples int
+}
+
+// DefaultMetricsHalfLife is how long it takes a request outcome to count
+// half as much toward a provider's error rate
+const DefaultMetricsHalfLife = 24 * time.Hour
+
+// minReliabilityRequests is how many recent requests a provider needs
+// before its error rate affects its score
+const minReliabilityRequests = 3
+
+// requestOutcomes counts a provider's requests and failures, each decayed
+// by its age so that old outcomes fade
+type requestOutcomes struct {
+	requests float64
+	failures float64
+	at       time.Time // When the counts were last decayed
+}
+
+// decayed returns the counts as they stand at now
+func (o requestOutcomes) decayed(now time.Time, halfLife time.Duration) requestOutcomes {
+	if halfLife <= 0 || o.at.IsZero() || !now.After(o.at) {
+		return o
+	}
+	factor := math.Pow(0.5, float64(now.Sub(o.at))/float64(halfLife))
+	return requestOutcomes{requests: o.requests * factor, failures: o.failures * factor, at: now}
+}
+
+// errorRate is the share of the counted requests that failed
+func (o requestOutcomes) errorRate() float64 {
+	if o.requests <= 0 {
+		return 0
+	}
+	return clamp(o.failures/o.requests, 0, 1)
+}
+
 // SetWeights updates the scoring weights.
 func (s *Scorer) SetWeights(w ScoringWeights) {
 	s.mu.Lock()
@@ -81,6 +138,31 @@ func (s *Scorer) SetWeights(w ScoringWeights) {
 	s.weights = w
 }
 
+// SetHalfLife sets how long it takes a request outcome to count half as
+// much; 0 stops outcomes from decaying.
+func (s *Scorer) SetHalfLife(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.halfLife = d
+}
+
+// RecordRequest counts a request's outcome toward a provider's error rate.
+// The provider's score is recalculated with its next metrics update.
+func (s *Scorer) RecordRequest(providerID string, success bool, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := s.outcomes[providerID].decayed(at, s.halfLife)
+	o.requests++
+	if !success {
+		o.failures++
+	}
+	if o.at.IsZero() || at.After(o.at) {
+		o.at = at
+	}
+	s.outcomes[providerID] = o
+}
+
 // GetWeights returns the current scoring weights.
 func (s *Scorer) GetWeights() ScoringWeights {
 	s.mu.RLock()
@@ -121,9 +203,18 @@ func (s *Scorer) UpdateProviderMetrics(
 		CostPerMToken:       costPerMToken,
 		LastUpdated:         time.Now(),
 	}
+	if q, ok := s.quality[providerID]; ok {
+		score.Quality = q.quality
+		score.QualitySamples = q.samples
+	}
+	o := s.outcomes[providerID].decayed(score.LastUpdated, s.halfLife)
+	score.ErrorRate = o.errorRate()
+	score.Requests = o.requests
 
 	// Calculate component scores (0-100 scale)
 	score.ModelSizeScore = s.scoreModelSize(modelParamsB)
+	score.QualityScore = s.scoreQuality(providerID)
+	score.ReliabilityScore = s.scoreReliability(o)
 	score.RoundTripScore = s.scoreRoundTrip(heartbeatLatencyMs)
 	score.RequestLatencyScore = s.scoreRequestLatency(avgRequestLatencyMs)
 	score.CostScore = s.scoreCost(costPerMToken)
@@ -135,6 +226,28 @@ func (s *Scorer) UpdateProviderMetrics(
 	return score
 }
 
+// SetProviderQuality records the mean judge grade (0-1) of a provider's
+// work over samples evaluations and recalculates its score. Providers never
+// graded score a neutral 50 on quality.
+func (s *Scorer) SetProviderQuality(providerID string, quality float64, samples int) *ProviderScore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.quality[providerID] = providerQuality{quality: clamp(quality, 0, 1), samples: samples}
+	score, ok := s.scores[providerID]
+	if !ok {
+		return nil
+	}
+	updated := *score
+	updated.Quality = s.quality[providerID].quality
+	updated.QualitySamples = samples
+	updated.QualityScore = s.scoreQuality(providerID)
+	updated.CompositeScore = s.calculateComposite(&updated)
+	updated.LastUpdated = time.Now()
+	s.scores[providerID] = &updated
+	return &updated
+}
+
 // GetScore returns the current score for a provider.
 func (s *Scorer) GetScore(providerID string) (*ProviderScore, bool) {
 	s.mu.RLock()
@@ -169,6 +282,25 @@ func (s *Scorer) scoreModelSize(paramsB float64) float64 {
 	return clamp(score, 0, 100)
 }
 
+// scoreQuality converts a provider's judged quality to a 0-100 score.
+// Providers that have not been graded score a neutral 50.
+func (s *Scorer) scoreQuality(providerID string) float64 {
+	q, ok := s.quality[providerID]
+	if !ok {
+		return 50
+	}
+	return clamp(q.quality*100, 0, 100)
+}
+
+// scoreReliability converts a provider's recent error rate to a 0-100
+// score. Providers with too few recent requests score 100.
+func (s *Scorer) scoreReliability(o requestOutcomes) float64 {
+	if o.requests < minReliabilityRequests {
+		return 100
+	}
+	return clamp((1-o.errorRate())*100, 0, 100)
+}
+
 // scoreRoundTrip converts heartbeat latency to a 0-100 score.
 // Lower latency scores higher.
 func (s *Scorer) scoreRoundTrip(latencyMs int64) float64 {
@@ -214,6 +346,8 @@ func (s *Scorer) calculateComposite(score *ProviderScore) float64 {
 	// This gives each factor a maximum contribution equal to its weight.
 	composite := 0.0
 	composite += s.weights.ModelSize * (score.ModelSizeScore / 100)
+	composite += s.weights.Quality * (score.QualityScore / 100)
+	composite += s.weights.Reliability * (score.ReliabilityScore / 100)
 	composite += s.weights.RoundTrip * (score.RoundTripScore / 100)
 	composite += s.weights.RequestLatency * (score.RequestLatencyScore / 100)
 	composite += s.weights.Cost * (score.CostScore / 100)
@@ -359,6 +493,8 @@ func (s *Scorer) RemoveProvider(providerID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	delete(s.scores, providerID)
+	delete(s.quality, providerID)
+	delete(s.outcomes, providerID)
 }
 
 // clamp restricts a value to a range.
@@ -3,6 +3,7 @@ package provider
 import (
 	"math"
 	"testing"
+	"time"
 )
 
 func TestDefaultWeights(t *testing.T) {
@@ -232,3 +233,70 @@ func TestScorerDynamicNormalization(t *testing.T) {
 			score2.ModelSizeScore, score1After.ModelSizeScore)
 	}
 }
+
+func TestScorerProviderQuality(t *testing.T) {
+	s := NewScorer()
+
+	// Same model and latency; only the judges' grades differ
+	s.UpdateProviderMetrics("providerA", 70, 100, 500, 0)
+	s.UpdateProviderMetrics("providerB", 70, 100, 500, 0)
+	ungraded := s.GetCompositeScore("providerA")
+
+	scoreA := s.SetProviderQuality("providerA", 0.9, 5)
+	scoreB := s.SetProviderQuality("providerB", 0.3, 5)
+	if scoreA == nil || scoreB == nil {
+		t.Fatal("expected scores for known providers")
+	}
+	if scoreA.CompositeScore <= ungraded || scoreB.CompositeScore >= ungraded {
+		t.Errorf("quality should move scores around the neutral %f: A=%f, B=%f",
+			ungraded, scoreA.CompositeScore, scoreB.CompositeScore)
+	}
+	if ranked := s.RankProviders([]string{"providerB", "providerA"}); ranked[0] != "providerA" {
+		t.Errorf("expected better graded provider first, got %v", ranked)
+	}
+
+	// Quality recorded before metrics applies once metrics arrive
+	if s.SetProviderQuality("providerC", 1, 3) != nil {
+		t.Error("expected no score for a provider without metrics")
+	}
+	if scoreC := s.UpdateProviderMetrics("providerC", 70, 100, 500, 0); scoreC.QualityScore != 100 || scoreC.QualitySamples != 3 {
+		t.Errorf("expected stored quality to apply, got %+v", scoreC)
+	}
+}
+
+func TestScorerReliability(t *testing.T) {
+	s := NewScorer()
+	now := time.Now()
+
+	for i := 0; i < 4; i++ {
+		s.RecordRequest("failing", false, now)
+		s.RecordRequest("steady", true, now)
+	}
+	failing := s.UpdateProviderMetrics("failing", 70, 100, 500, 0)
+	steady := s.UpdateProviderMetrics("steady", 70, 100, 500, 0)
+	unknown := s.UpdateProviderMetrics("unknown", 70, 100, 500, 0)
+	if failing.ErrorRate != 1 || failing.ReliabilityScore != 0 {
+		t.Errorf("failing provider: error rate %f, reliability %f", failing.ErrorRate, failing.ReliabilityScore)
+	}
+	if steady.ReliabilityScore != 100 || unknown.ReliabilityScore != 100 {
+		t.Errorf("expected full reliability, got steady=%f unknown=%f", steady.ReliabilityScore, unknown.ReliabilityScore)
+	}
+	if ranked := s.RankProviders([]string{"failing", "steady"}); ranked[0] != "steady" {
+		t.Errorf("expected the reliable provider first, got %v", ranked)
+	}
+
+	// Three half-lives on, four failures count as half a request: too few
+	// to hold against the provider
+	for i := 0; i < 4; i++ {
+		s.RecordRequest("recovered", false, now.Add(-3*DefaultMetricsHalfLife))
+	}
+	recovered := s.UpdateProviderMetrics("recovered", 70, 100, 500, 0)
+	if math.Abs(recovered.Requests-0.5) > 0.01 || recovered.ReliabilityScore != 100 {
+		t.Errorf("expected old failures to decay, got requests %f, reliability %f", recovered.Requests, recovered.ReliabilityScore)
+	}
+
+	s.RemoveProvider("failing")
+	if o := s.outcomesAt("failing", now); o.requests != 0 {
+		t.Errorf("expected outcomes to be removed, got %+v", o)
+	}
+}
@@ -0,0 +1,240 @@
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Self-hosted inference server types. Both serve the OpenAI chat API under
+// /v1 and expose their health and scheduler metrics at the server root.
+const (
+	TypeVLLM = "vllm"
+	TypeTGI  = "tgi" // HuggingFace Text Generation Inference
+)
+
+// serverLoadTTL is how long a reported load is trusted without a newer one
+const serverLoadTTL = 5 * time.Minute
+
+// ServerLoad is the scheduler state an inference server reports
+type ServerLoad struct {
+	Running    int       `json:"running"`               // Requests being generated
+	Waiting    int       `json:"waiting"`               // Requests queued on the server
+	CacheUsage float64   `json:"cache_usage,omitempty"` // Share of the KV cache in use, 0-1; 0 when unreported
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// SaturationConfig sets when an inference server counts as saturated.
+// Zero values use the defaults.
+type SaturationConfig struct {
+	MaxWaiting    int     // Requests queued on the server; default 8
+	MaxCacheUsage float64 // Share of the KV cache in use; default 0.95
+}
+
+func (c SaturationConfig) withDefaults() SaturationConfig {
+	if c.MaxWaiting <= 0 {
+		c.MaxWaiting = 8
+	}
+	if c.MaxCacheUsage <= 0 {
+		c.MaxCacheUsage = 0.95
+	}
+	return c
+}
+
+// Saturated reports whether the load is at or over the thresholds of cfg
+func (l ServerLoad) Saturated(cfg SaturationConfig) bool {
+	cfg = cfg.withDefaults()
+	return l.Waiting >= cfg.MaxWaiting || l.CacheUsage >= cfg.MaxCacheUsage
+}
+
+// LoadReporter is implemented by protocols of inference servers that
+// report their health and load, which heartbeats read
+type LoadReporter interface {
+	Health(ctx context.Context) error
+	Load(ctx context.Context) (ServerLoad, error)
+}
+
+// SelfHostedProvider implements Protocol for vLLM and TGI servers: chat
+// requests go through the OpenAI-compatible API, while health, load and,
+// for TGI, the served model are read from the server's own endpoints.
+type SelfHostedProvider struct {
+	*OpenAIProvider
+	serverType string
+	root       string // Server root, the endpoint without /v1
+}
+
+// NewSelfHostedProvider creates a provider for a vLLM or TGI server whose
+// OpenAI-compatible API is at endpoint
+func NewSelfHostedProvider(serverType, endpoint, apiKey string) *SelfHostedProvider {
+	endpoint = strings.TrimSuffix(endpoint, "/")
+	return &SelfHostedProvider{
+		OpenAIProvider: NewOpenAIProvider(endpoint, apiKey),
+		serverType:     serverType,
+		root:           strings.TrimSuffix(endpoint, "/v1"),
+	}
+}
+
+// GetModels lists the served models. TGI serves one model, which its info
+// endpoint names along with its context length.
+func (p *SelfHostedProvider) GetModels(ctx context.Context) ([]Model, error) {
+	if p.serverType != TypeTGI {
+		return p.OpenAIProvider.GetModels(ctx)
+	}
+	body, err := p.get(ctx, "/info")
+	if err != nil {
+		return nil, err
+	}
+	var info struct {
+		ModelID        string `json:"model_id"`
+		MaxInputTokens int    `json:"max_input_tokens"`
+		MaxTotalTokens int    `json:"max_total_tokens"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if info.ModelID == "" {
+		return nil, fmt.Errorf("tgi server names no model")
+	}
+	return []Model{{ID: info.ModelID, Object: "model", OwnedBy: "tgi", MaxModelLen: info.MaxTotalTokens}}, nil
+}
+
+// Health checks the server's health endpoint, which fails while the engine
+// is down even if the API still answers
+func (p *SelfHostedProvider) Health(ctx context.Context) error {
+	_, err := p.get(ctx, "/health")
+	return err
+}
+
+// Load reads the server's queue depth and cache usage from its Prometheus
+// metrics
+func (p *SelfHostedProvider) Load(ctx context.Context) (ServerLoad, error) {
+	body, err := p.get(ctx, "/metrics")
+	if err != nil {
+		return ServerLoad{}, err
+	}
+	samples := parsePrometheusText(string(body))
+	load := ServerLoad{CheckedAt: time.Now()}
+	switch p.serverType {
+	case TypeTGI:
+		load.Running = int(samples["tgi_batch_current_size"])
+		load.Waiting = int(samples["tgi_queue_size"])
+	default:
+		load.Running = int(samples["vllm:num_requests_running"])
+		load.Waiting = int(samples["vllm:num_requests_waiting"])
+		// Renamed from gpu_cache_usage_perc in vLLM 0.10
+		load.CacheUsage = samples["vllm:gpu_cache_usage_perc"]
+		if usage, ok := samples["vllm:kv_cache_usage_perc"]; ok {
+			load.CacheUsage = usage
+		}
+	}
+	return load, nil
+}
+
+// get reads an endpoint of the server root
+func (p *SelfHostedProvider) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.root+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s returned status %d: %s", p.serverType, path, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// parsePrometheusText sums the samples of each metric in the Prometheus
+// text format across their label sets
+func parsePrometheusText(text string) map[string]float64 {
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := line
+		rest := ""
+		if i := strings.IndexAny(line, "{ "); i >= 0 {
+			name = line[:i]
+			rest = line[i:]
+		}
+		if strings.HasPrefix(rest, "{") {
+			end := strings.LastIndex(rest, "}")
+			if end < 0 {
+				continue
+			}
+			rest = rest[end+1:]
+		}
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		samples[name] += value
+	}
+	return samples
+}
+
+// SetSaturationConfig sets when inference servers count as saturated
+func (r *Registry) SetSaturationConfig(cfg SaturationConfig) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.saturation = cfg
+}
+
+// SetServerLoad records the load a provider's server last reported
+func (r *Registry) SetServerLoad(providerID string, load ServerLoad) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.loads == nil {
+		r.loads = make(map[string]ServerLoad)
+	}
+	r.loads[providerID] = load
+}
+
+// ServerLoad returns the load a provider's server last reported, if it
+// reported one recently
+func (r *Registry) ServerLoad(providerID string) (ServerLoad, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	load, ok := r.loads[providerID]
+	if !ok || time.Since(load.CheckedAt) > serverLoadTTL {
+		return ServerLoad{}, false
+	}
+	return load, true
+}
+
+// IsSaturated reports whether a provider's server recently reported a load
+// over the saturation thresholds
+func (r *Registry) IsSaturated(providerID string) bool {
+	load, ok := r.ServerLoad(providerID)
+	if !ok {
+		return false
+	}
+	r.mu.RLock()
+	cfg := r.saturation
+	r.mu.RUnlock()
+	return load.Saturated(cfg)
+}
@@ -0,0 +1,104 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const vllmMetrics = `# HELP vllm:num_requests_running Number of requests currently running on GPU.
+# TYPE vllm:num_requests_running gauge
+vllm:num_requests_running{model_name="Qwen/Qwen2.5-Coder-32B-Instruct"} 14.0
+# HELP vllm:num_requests_waiting Number of requests waiting to be processed.
+# TYPE vllm:num_requests_waiting gauge
+vllm:num_requests_waiting{model_name="Qwen/Qwen2.5-Coder-32B-Instruct"} 9.0
+vllm:kv_cache_usage_perc{model_name="Qwen/Qwen2.5-Coder-32B-Instruct"} 0.42
+vllm:prompt_tokens_total{model_name="Qwen/Qwen2.5-Coder-32B-Instruct"} 1.234e+06
+`
+
+func TestSelfHostedProvider_VLLMLoad(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/metrics":
+			fmt.Fprint(w, vllmMetrics)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := NewSelfHostedProvider(TypeVLLM, server.URL+"/v1/", "")
+	if err := p.Health(context.Background()); err != nil {
+		t.Fatalf("Health: %v", err)
+	}
+	load, err := p.Load(context.Background())
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if load.Running != 14 || load.Waiting != 9 || load.CacheUsage != 0.42 {
+		t.Errorf("unexpected load %+v", load)
+	}
+	if !load.Saturated(SaturationConfig{}) || load.Saturated(SaturationConfig{MaxWaiting: 10}) {
+		t.Error("expected 9 waiting requests to saturate only with the default threshold")
+	}
+}
+
+func TestSelfHostedProvider_TGI(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/health":
+			if !healthy {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		case "/info":
+			fmt.Fprint(w, `{"model_id":"bigcode/starcoder2-15b","max_input_tokens":8191,"max_total_tokens":8192}`)
+		case "/metrics":
+			fmt.Fprint(w, "tgi_queue_size 3\ntgi_batch_current_size 32\n")
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	p := NewSelfHostedProvider(TypeTGI, server.URL+"/v1", "")
+	models, err := p.GetModels(context.Background())
+	if err != nil || len(models) != 1 || models[0].ID != "bigcode/starcoder2-15b" || models[0].MaxModelLen != 8192 {
+		t.Fatalf("expected the served model, got %+v, %v", models, err)
+	}
+	load, err := p.Load(context.Background())
+	if err != nil || load.Waiting != 3 || load.Running != 32 {
+		t.Errorf("unexpected load %+v, %v", load, err)
+	}
+
+	healthy = false
+	if err := p.Health(context.Background()); err == nil {
+		t.Error("expected an unhealthy server to fail its health check")
+	}
+}
+
+func TestRegistryServerLoad(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Register(&ProviderConfig{ID: "vllm", Type: TypeVLLM, Endpoint: "http://localhost:8000/v1"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, ok := r.ServerLoad("vllm"); ok || r.IsSaturated("vllm") {
+		t.Fatal("expected no load before a heartbeat reports one")
+	}
+
+	r.SetSaturationConfig(SaturationConfig{MaxWaiting: 2})
+	r.SetServerLoad("vllm", ServerLoad{Waiting: 2, CheckedAt: time.Now()})
+	if !r.IsSaturated("vllm") {
+		t.Error("expected the configured threshold to apply")
+	}
+
+	_ = r.Unregister("vllm")
+	if _, ok := r.ServerLoad("vllm"); ok {
+		t.Error("expected the load to go with the provider")
+	}
+}
@@ -75,11 +75,7 @@ func (p *OpenAIProvider) CreateChatCompletionStream(ctx context.Context, req *Ch
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
 		respBody, _ := io.ReadAll(resp.Body)
-		bodyStr := string(respBody)
-		if resp.StatusCode == http.StatusBadRequest && isContextLengthError(bodyStr) {
-			return &ContextLengthError{StatusCode: resp.StatusCode, Body: bodyStr}
-		}
-		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, bodyStr)
+		return chatCompletionError(req, resp.StatusCode, string(respBody))
 	}
 
 	// Read streaming response
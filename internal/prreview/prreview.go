@@ -0,0 +1,468 @@
+// Package prreview is the automated review stage pull requests go through
+// before human review. When an agent opens a pull request, a reviewer
+// persona on a configurable provider reads the diff and answers with
+// structured findings, blocking or not. Every finding is posted on the pull
+// request as a comment, the review is submitted as an approval or a change
+// request, and auto-merge, when enabled, only happens after a clean review.
+package prreview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Finding severities
+const (
+	SeverityBlocking    = "blocking"     // Must be fixed before the pull request merges
+	SeverityNonBlocking = "non_blocking" // A suggestion; the pull request may merge as is
+)
+
+// Review statuses
+const (
+	StatusRunning          = "running"
+	StatusClean            = "clean"             // No blocking findings
+	StatusChangesRequested = "changes_requested" // At least one blocking finding
+	StatusError            = "error"             // The diff or the reviewer's answer could not be had
+)
+
+// Review events submitted on the pull request
+const (
+	EventApprove        = "APPROVE"
+	EventRequestChanges = "REQUEST_CHANGES"
+)
+
+// maxResponseChars bounds the reviewer's answer kept with a review
+const maxResponseChars = 8000
+
+// Finding is one problem or suggestion the reviewer raised
+type Finding struct {
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Message  string `json:"message"`
+}
+
+// PullRequest identifies the pull request to review
+type PullRequest struct {
+	ProjectID string `json:"project_id"`
+	BeadID    string `json:"bead_id,omitempty"`
+	Number    int    `json:"number"`
+	URL       string `json:"url,omitempty"`
+	Branch    string `json:"branch"`
+	Base      string `json:"base"`
+	Title     string `json:"title,omitempty"`
+}
+
+// Review is one automated review of a pull request
+type Review struct {
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"project_id"`
+	BeadID     string    `json:"bead_id,omitempty"`
+	PRNumber   int       `json:"pr_number"`
+	PRURL      string    `json:"pr_url,omitempty"`
+	Branch     string    `json:"branch"`
+	Base       string    `json:"base"`
+	Persona    string    `json:"persona"`
+	ProviderID string    `json:"provider_id,omitempty"`
+	Model      string    `json:"model,omitempty"`
+	Status     string    `json:"status"`
+	Summary    string    `json:"summary,omitempty"`
+	Findings   []Finding `json:"findings"`
+	Blocking   int       `json:"blocking"`
+	Response   string    `json:"response,omitempty"` // The reviewer's raw answer
+	Merged     bool      `json:"merged"`
+	MergeError string    `json:"merge_error,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+}
+
+// Store persists reviews
+type Store interface {
+	// SavePRReview inserts or replaces a review
+	SavePRReview(r *Review) error
+	// GetPRReview returns a review, or nil if unknown
+	GetPRReview(id string) (*Review, error)
+	// ListPRReviews returns a project's reviews, newest first; limit 0
+	// means no limit
+	ListPRReviews(projectID string, limit int) ([]*Review, error)
+}
+
+// Differ fetches the changes a pull request makes
+type Differ interface {
+	// Diff returns the diff of branch against base in a project
+	Diff(ctx context.Context, projectID, base, branch string) (string, error)
+}
+
+// Completer asks the reviewer persona for its review
+type Completer interface {
+	// Complete answers prompt as persona, on providerID or on a provider
+	// suited to the persona when empty, and names the provider and model
+	// that did
+	Complete(ctx context.Context, persona, providerID, prompt string) (response, usedProvider, model string, err error)
+}
+
+// Publisher posts reviews on pull requests
+type Publisher interface {
+	// Comment posts a comment, on a line of a file when path is set
+	Comment(ctx context.Context, projectID string, number int, path string, line int, body string) error
+	// Submit submits a review with event APPROVE or REQUEST_CHANGES
+	Submit(ctx context.Context, projectID string, number int, event, body string) error
+	// Merge merges the pull request with method merge, squash or rebase
+	Merge(ctx context.Context, projectID string, number int, method string) error
+}
+
+// Config tunes the review stage
+type Config struct {
+	Enabled      bool          // Review every pull request agents open
+	Persona      string        // Reviewer persona
+	ProviderID   string        // Provider to review on; empty picks one by the persona's model tier
+	AutoMerge    bool          // Merge pull requests whose review is clean
+	MergeMethod  string        // merge, squash or rebase
+	MaxDiffBytes int           // Longer diffs are truncated before review
+	Timeout      time.Duration // Per review
+}
+
+// DefaultConfig reviews with the code reviewer persona, squash-merges when
+// auto-merge is on, and sends at most 200KB of diff
+func DefaultConfig() Config {
+	return Config{
+		Persona:      "default/code-reviewer",
+		MergeMethod:  "squash",
+		MaxDiffBytes: 200000,
+		Timeout:      5 * time.Minute,
+	}
+}
+
+// Manager runs reviews. Opened does nothing on a nil Manager.
+type Manager struct {
+	store     Store
+	differ    Differ
+	completer Completer
+	publisher Publisher
+	cfg       Config
+
+	mu         sync.Mutex
+	running    map[string]bool // Pull requests under review, by project and number
+	onReviewed func(*Review)
+	mergeGate  func(ctx context.Context, pr PullRequest) error
+}
+
+// NewManager creates a manager backed by store that reads diffs through
+// differ, reviews them through completer and posts the reviews through
+// publisher
+func NewManager(store Store, differ Differ, completer Completer, publisher Publisher, cfg Config) *Manager {
+	if store == nil || differ == nil || completer == nil || publisher == nil {
+		return nil
+	}
+	def := DefaultConfig()
+	if cfg.Persona == "" {
+		cfg.Persona = def.Persona
+	}
+	if cfg.MergeMethod == "" {
+		cfg.MergeMethod = def.MergeMethod
+	}
+	if cfg.MaxDiffBytes <= 0 {
+		cfg.MaxDiffBytes = def.MaxDiffBytes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = def.Timeout
+	}
+	return &Manager{
+		store:     store,
+		differ:    differ,
+		completer: completer,
+		publisher: publisher,
+		cfg:       cfg,
+		running:   make(map[string]bool),
+	}
+}
+
+// Config returns the configuration the manager runs with
+func (m *Manager) Config() Config {
+	return m.cfg
+}
+
+// OnReviewed registers the function told about every finished review
+func (m *Manager) OnReviewed(fn func(*Review)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onReviewed = fn
+}
+
+// SetMergeGate registers the function asked before a clean review
+// auto-merges its pull request. An error keeps the pull request open and
+// is recorded as the review's merge error.
+func (m *Manager) SetMergeGate(fn func(ctx context.Context, pr PullRequest) error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mergeGate = fn
+}
+
+// Opened reviews a pull request an agent just opened, in the background,
+// when the review stage is enabled
+func (m *Manager) Opened(pr PullRequest) {
+	if m == nil || !m.cfg.Enabled {
+		return
+	}
+	go func() {
+		if _, err := m.Review(context.Background(), pr); err != nil {
+			log.Printf("[PRReview] Review of %s#%d failed: %v", pr.ProjectID, pr.Number, err)
+		}
+	}()
+}
+
+// Get returns one of a project's reviews
+func (m *Manager) Get(projectID, id string) (*Review, error) {
+	r, err := m.store.GetPRReview(id)
+	if err != nil {
+		return nil, err
+	}
+	if r == nil || r.ProjectID != projectID {
+		return nil, fmt.Errorf("pull request review not found: %s", id)
+	}
+	return r, nil
+}
+
+// List returns a project's most recent reviews, newest first
+func (m *Manager) List(projectID string, limit int) ([]*Review, error) {
+	return m.store.ListPRReviews(projectID, limit)
+}
+
+// Review reviews a pull request now, posts the findings and the verdict on
+// it, merges it if the review is clean and auto-merge is on, and records
+// the review. A review that could not be completed is recorded with status
+// error and returned along with the error.
+func (m *Manager) Review(ctx context.Context, pr PullRequest) (*Review, error) {
+	if pr.ProjectID == "" {
+		return nil, fmt.Errorf("project_id must not be empty")
+	}
+	if pr.Number < 1 {
+		return nil, fmt.Errorf("pr_number must be positive")
+	}
+	if pr.Branch == "" {
+		return nil, fmt.Errorf("branch must not be empty")
+	}
+	if pr.Base == "" {
+		pr.Base = "main"
+	}
+
+	key := fmt.Sprintf("%s#%d", pr.ProjectID, pr.Number)
+	m.mu.Lock()
+	if m.running[key] {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("pull request %s is already under review", key)
+	}
+	m.running[key] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, key)
+		m.mu.Unlock()
+	}()
+
+	review := &Review{
+		ID:         uuid.New().String(),
+		ProjectID:  pr.ProjectID,
+		BeadID:     pr.BeadID,
+		PRNumber:   pr.Number,
+		PRURL:      pr.URL,
+		Branch:     pr.Branch,
+		Base:       pr.Base,
+		Persona:    m.cfg.Persona,
+		ProviderID: m.cfg.ProviderID,
+		Status:     StatusRunning,
+		Findings:   []Finding{},
+		StartedAt:  time.Now().UTC(),
+	}
+	err := m.review(ctx, pr, review)
+	if err != nil {
+		review.Status = StatusError
+		review.Error = err.Error()
+	}
+	review.FinishedAt = time.Now().UTC()
+	if serr := m.store.SavePRReview(review); serr != nil {
+		return nil, serr
+	}
+
+	m.mu.Lock()
+	notify := m.onReviewed
+	m.mu.Unlock()
+	if notify != nil {
+		notify(review)
+	}
+	return review, err
+}
+
+// review fetches the diff, asks the reviewer and publishes its findings
+func (m *Manager) review(ctx context.Context, pr PullRequest, review *Review) error {
+	cctx, cancel := context.WithTimeout(ctx, m.cfg.Timeout)
+	defer cancel()
+
+	diff, err := m.differ.Diff(cctx, pr.ProjectID, pr.Base, pr.Branch)
+	if err != nil {
+		return fmt.Errorf("failed to fetch diff: %w", err)
+	}
+	if strings.TrimSpace(diff) == "" {
+		return fmt.Errorf("%s has no changes against %s", pr.Branch, pr.Base)
+	}
+
+	response, providerID, model, err := m.completer.Complete(cctx, m.cfg.Persona, m.cfg.ProviderID, m.prompt(pr, diff))
+	if providerID != "" {
+		review.ProviderID = providerID
+	}
+	review.Model = model
+	if err != nil {
+		return fmt.Errorf("reviewer failed: %w", err)
+	}
+	if len(response) > maxResponseChars {
+		review.Response = response[:maxResponseChars]
+	} else {
+		review.Response = response
+	}
+
+	summary, findings, err := ParseFindings(response)
+	if err != nil {
+		return err
+	}
+	review.Summary = summary
+	review.Findings = findings
+	for _, f := range findings {
+		if f.Severity == SeverityBlocking {
+			review.Blocking++
+		}
+	}
+	if review.Blocking > 0 {
+		review.Status = StatusChangesRequested
+	} else {
+		review.Status = StatusClean
+	}
+
+	for _, f := range findings {
+		if err := m.publisher.Comment(cctx, pr.ProjectID, pr.Number, f.Path, f.Line, formatFinding(f)); err != nil {
+			return fmt.Errorf("failed to post finding: %w", err)
+		}
+	}
+	event := EventApprove
+	if review.Status == StatusChangesRequested {
+		event = EventRequestChanges
+	}
+	if err := m.publisher.Submit(cctx, pr.ProjectID, pr.Number, event, formatVerdict(review)); err != nil {
+		return fmt.Errorf("failed to submit review: %w", err)
+	}
+
+	if review.Status == StatusClean && m.cfg.AutoMerge {
+		m.mu.Lock()
+		gate := m.mergeGate
+		m.mu.Unlock()
+		if gate != nil {
+			if err := gate(cctx, pr); err != nil {
+				review.MergeError = err.Error()
+				return nil
+			}
+		}
+		if err := m.publisher.Merge(cctx, pr.ProjectID, pr.Number, m.cfg.MergeMethod); err != nil {
+			review.MergeError = err.Error()
+		} else {
+			review.Merged = true
+		}
+	}
+	return nil
+}
+
+// prompt asks for a review of diff, truncated to the configured size
+func (m *Manager) prompt(pr PullRequest, diff string) string {
+	truncated := ""
+	if len(diff) > m.cfg.MaxDiffBytes {
+		diff = diff[:m.cfg.MaxDiffBytes]
+		truncated = "\n(The diff was truncated; review what is shown.)\n"
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("Review pull request #%d, which merges %s into %s.\n", pr.Number, pr.Branch, pr.Base))
+	if pr.Title != "" {
+		sb.WriteString(fmt.Sprintf("Title: %s\n", pr.Title))
+	}
+	sb.WriteString("\nReport bugs, security problems, missing tests and unclear code. A finding is blocking when the\n")
+	sb.WriteString("pull request must not merge until it is fixed, and non_blocking when it is a suggestion.\n\n")
+	sb.WriteString("Answer with JSON only, in this shape:\n")
+	sb.WriteString(`{"summary": "one paragraph", "findings": [{"severity": "blocking|non_blocking", "path": "file", "line": 12, "message": "what is wrong and how to fix it"}]}`)
+	sb.WriteString("\nAn empty findings list approves the pull request.\n\n")
+	sb.WriteString("```diff\n")
+	sb.WriteString(diff)
+	sb.WriteString("\n```\n")
+	sb.WriteString(truncated)
+	return sb.String()
+}
+
+// ParseFindings reads the reviewer's JSON answer, which may be wrapped in a
+// code fence or surrounded by prose. Severities other than blocking count
+// as non-blocking.
+func ParseFindings(response string) (string, []Finding, error) {
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return "", nil, fmt.Errorf("reviewer answer has no JSON object")
+	}
+	var answer struct {
+		Summary  string    `json:"summary"`
+		Findings []Finding `json:"findings"`
+	}
+	if err := json.Unmarshal([]byte(response[start:end+1]), &answer); err != nil {
+		return "", nil, fmt.Errorf("failed to decode reviewer answer: %w", err)
+	}
+	findings := make([]Finding, 0, len(answer.Findings))
+	for _, f := range answer.Findings {
+		f.Message = strings.TrimSpace(f.Message)
+		if f.Message == "" {
+			continue
+		}
+		severity := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(f.Severity), "-", "_"))
+		if severity != SeverityBlocking {
+			severity = SeverityNonBlocking
+		}
+		f.Severity = severity
+		findings = append(findings, f)
+	}
+	return strings.TrimSpace(answer.Summary), findings, nil
+}
+
+// formatFinding renders a finding as a pull request comment
+func formatFinding(f Finding) string {
+	label := "Suggestion"
+	if f.Severity == SeverityBlocking {
+		label = "Blocking"
+	}
+	if f.Path != "" && f.Line > 0 {
+		return fmt.Sprintf("**%s** (%s:%d): %s", label, f.Path, f.Line, f.Message)
+	}
+	if f.Path != "" {
+		return fmt.Sprintf("**%s** (%s): %s", label, f.Path, f.Message)
+	}
+	return fmt.Sprintf("**%s**: %s", label, f.Message)
+}
+
+// formatVerdict renders the body of the submitted review
+func formatVerdict(r *Review) string {
+	var sb strings.Builder
+	sb.WriteString("Automated review")
+	if r.Persona != "" {
+		sb.WriteString(" by " + r.Persona)
+	}
+	sb.WriteString(": ")
+	if r.Blocking > 0 {
+		sb.WriteString(fmt.Sprintf("%d blocking and %d non-blocking finding(s).", r.Blocking, len(r.Findings)-r.Blocking))
+	} else {
+		sb.WriteString(fmt.Sprintf("no blocking findings, %d suggestion(s).", len(r.Findings)))
+	}
+	if r.Summary != "" {
+		sb.WriteString("\n\n" + r.Summary)
+	}
+	return sb.String()
+}
@@ -0,0 +1,241 @@
+package prreview_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/prreview"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "prreview.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakeHost serves diffs and answers and records what gets posted
+type fakeHost struct {
+	mu       sync.Mutex
+	diff     string
+	answer   string
+	comments []string
+	events   []string
+	merged   []string
+	mergeErr error
+}
+
+func (f *fakeHost) Diff(ctx context.Context, projectID, base, branch string) (string, error) {
+	if f.diff == "" {
+		return "", fmt.Errorf("unknown branch %s", branch)
+	}
+	return f.diff, nil
+}
+
+func (f *fakeHost) Complete(ctx context.Context, persona, providerID, prompt string) (string, string, string, error) {
+	if !strings.Contains(prompt, f.diff) {
+		return "", "", "", fmt.Errorf("prompt is missing the diff")
+	}
+	return f.answer, "p1", "m1", nil
+}
+
+func (f *fakeHost) Comment(ctx context.Context, projectID string, number int, path string, line int, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.comments = append(f.comments, body)
+	return nil
+}
+
+func (f *fakeHost) Submit(ctx context.Context, projectID string, number int, event, body string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, event)
+	return nil
+}
+
+func (f *fakeHost) Merge(ctx context.Context, projectID string, number int, method string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.mergeErr != nil {
+		return f.mergeErr
+	}
+	f.merged = append(f.merged, method)
+	return nil
+}
+
+func newTestManager(t *testing.T, host *fakeHost, autoMerge bool) *prreview.Manager {
+	t.Helper()
+	return prreview.NewManager(newTestDB(t), host, host, host, prreview.Config{Enabled: true, AutoMerge: autoMerge})
+}
+
+var testPR = prreview.PullRequest{ProjectID: "proj-1", BeadID: "bd-1", Number: 7, Branch: "agent/bd-1", Base: "main"}
+
+func TestParseFindings(t *testing.T) {
+	summary, findings, err := prreview.ParseFindings("Here is my review:\n```json\n" +
+		`{"summary": "Mostly fine.", "findings": [` +
+		`{"severity": "Blocking", "path": "main.go", "line": 3, "message": "nil dereference"},` +
+		`{"severity": "non-blocking", "message": "rename x"},` +
+		`{"severity": "nit", "message": "typo"},` +
+		`{"severity": "blocking", "message": "  "}]}` + "\n```")
+	if err != nil {
+		t.Fatalf("ParseFindings failed: %v", err)
+	}
+	if summary != "Mostly fine." || len(findings) != 3 {
+		t.Fatalf("got %q, %+v", summary, findings)
+	}
+	if findings[0].Severity != prreview.SeverityBlocking || findings[1].Severity != prreview.SeverityNonBlocking || findings[2].Severity != prreview.SeverityNonBlocking {
+		t.Errorf("severities not normalized: %+v", findings)
+	}
+
+	if _, _, err := prreview.ParseFindings("Looks good to me!"); err == nil {
+		t.Error("expected an error for an answer without JSON")
+	}
+}
+
+func TestReview_CleanReviewAutoMerges(t *testing.T) {
+	host := &fakeHost{diff: "+fmt.Println(1)", answer: `{"summary": "ok", "findings": [{"severity": "non_blocking", "message": "add a test"}]}`}
+	m := newTestManager(t, host, true)
+	var notified *prreview.Review
+	m.OnReviewed(func(r *prreview.Review) { notified = r })
+
+	review, err := m.Review(context.Background(), testPR)
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if review.Status != prreview.StatusClean || review.Blocking != 0 || !review.Merged {
+		t.Errorf("expected a clean, merged review, got %+v", review)
+	}
+	if review.ProviderID != "p1" || review.Model != "m1" || review.Persona != "default/code-reviewer" {
+		t.Errorf("reviewer not recorded: %+v", review)
+	}
+	if len(host.comments) != 1 || !strings.Contains(host.comments[0], "Suggestion") {
+		t.Errorf("expected one suggestion comment, got %v", host.comments)
+	}
+	if len(host.events) != 1 || host.events[0] != prreview.EventApprove {
+		t.Errorf("expected an approval, got %v", host.events)
+	}
+	if len(host.merged) != 1 || host.merged[0] != "squash" {
+		t.Errorf("expected a squash merge, got %v", host.merged)
+	}
+	if notified == nil || notified.ID != review.ID {
+		t.Error("OnReviewed was not told about the review")
+	}
+
+	got, err := m.Get("proj-1", review.ID)
+	if err != nil || got.Status != prreview.StatusClean {
+		t.Fatalf("Get = %+v, %v", got, err)
+	}
+	if _, err := m.Get("proj-2", review.ID); err == nil {
+		t.Error("a review must not be visible from another project")
+	}
+}
+
+func TestReview_BlockingFindingsGateMerge(t *testing.T) {
+	host := &fakeHost{diff: "+os.RemoveAll(dir)", answer: `{"summary": "dangerous", "findings": [{"severity": "blocking", "path": "main.go", "line": 1, "message": "deletes the work directory"}]}`}
+	m := newTestManager(t, host, true)
+
+	review, err := m.Review(context.Background(), testPR)
+	if err != nil {
+		t.Fatalf("Review failed: %v", err)
+	}
+	if review.Status != prreview.StatusChangesRequested || review.Blocking != 1 || review.Merged {
+		t.Errorf("expected changes requested and no merge, got %+v", review)
+	}
+	if len(host.events) != 1 || host.events[0] != prreview.EventRequestChanges {
+		t.Errorf("expected a change request, got %v", host.events)
+	}
+	if len(host.merged) != 0 {
+		t.Errorf("a pull request with blocking findings must not merge, got %v", host.merged)
+	}
+	if !strings.Contains(host.comments[0], "main.go:1") {
+		t.Errorf("finding comment should name its line, got %q", host.comments[0])
+	}
+}
+
+func TestReview_AutoMergeOffAndMergeFailure(t *testing.T) {
+	host := &fakeHost{diff: "+x", answer: `{"summary": "fine", "findings": []}`}
+	review, err := newTestManager(t, host, false).Review(context.Background(), testPR)
+	if err != nil || review.Merged || len(host.merged) != 0 {
+		t.Fatalf("auto-merge off must not merge: %+v, %v", review, err)
+	}
+
+	host.mergeErr = fmt.Errorf("branch protection")
+	review, err = newTestManager(t, host, true).Review(context.Background(), testPR)
+	if err != nil {
+		t.Fatalf("a failed merge should not fail the review: %v", err)
+	}
+	if review.Merged || review.MergeError != "branch protection" || review.Status != prreview.StatusClean {
+		t.Errorf("merge failure not recorded: %+v", review)
+	}
+}
+
+func TestReview_MergeGate(t *testing.T) {
+	host := &fakeHost{diff: "+x", answer: `{"summary": "fine", "findings": []}`}
+	m := newTestManager(t, host, true)
+	m.SetMergeGate(func(ctx context.Context, pr prreview.PullRequest) error {
+		return fmt.Errorf("CI checks on %s have not finished: test", pr.Branch)
+	})
+	review, err := m.Review(context.Background(), testPR)
+	if err != nil {
+		t.Fatalf("a gated merge should not fail the review: %v", err)
+	}
+	if review.Merged || review.MergeError != "CI checks on agent/bd-1 have not finished: test" || len(host.merged) != 0 {
+		t.Errorf("expected the merge to be held back, got %+v", review)
+	}
+}
+
+func TestReview_ErrorsAreRecorded(t *testing.T) {
+	host := &fakeHost{diff: "+x", answer: "I could not review this."}
+	m := newTestManager(t, host, true)
+	review, err := m.Review(context.Background(), testPR)
+	if err == nil || review == nil || review.Status != prreview.StatusError || review.Error == "" {
+		t.Fatalf("expected a recorded error review, got %+v, %v", review, err)
+	}
+	if len(host.events) != 0 || len(host.merged) != 0 {
+		t.Error("nothing should be posted for a failed review")
+	}
+	list, _ := m.List("proj-1", 0)
+	if len(list) != 1 {
+		t.Errorf("expected the failed review to be listed, got %d", len(list))
+	}
+
+	if _, err := m.Review(context.Background(), prreview.PullRequest{ProjectID: "proj-1", Number: 1}); err == nil {
+		t.Error("expected an error without a branch")
+	}
+}
+
+func TestOpened(t *testing.T) {
+	var nilManager *prreview.Manager
+	nilManager.Opened(testPR)
+
+	host := &fakeHost{diff: "+x", answer: `{"findings": []}`}
+	disabled := prreview.NewManager(newTestDB(t), host, host, host, prreview.Config{})
+	disabled.Opened(testPR)
+
+	m := newTestManager(t, host, false)
+	done := make(chan *prreview.Review, 1)
+	m.OnReviewed(func(r *prreview.Review) { done <- r })
+	m.Opened(testPR)
+	select {
+	case r := <-done:
+		if r.Status != prreview.StatusClean {
+			t.Errorf("expected a clean review, got %+v", r)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Opened did not review the pull request")
+	}
+	host.mu.Lock()
+	defer host.mu.Unlock()
+	if len(host.events) != 1 {
+		t.Errorf("the disabled manager must not review, got %d reviews", len(host.events))
+	}
+}
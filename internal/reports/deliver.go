@@ -0,0 +1,348 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+)
+
+// deliveryTimeout bounds each Slack post and S3 upload
+const deliveryTimeout = 30 * time.Second
+
+// summaryText renders a report's headline figures as plain text
+func summaryText(r *Report, loc *time.Location) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n", r.Title, r.Period(loc))
+	for _, m := range r.Summary {
+		fmt.Fprintf(&b, "- %s: %s\n", m.Label, m.Value)
+	}
+	return b.String()
+}
+
+// EmailDeliverer mails a report as an attachment
+type EmailDeliverer struct {
+	smtp *analytics.SMTPConfig
+	send func(to []string, message []byte) error
+}
+
+// NewEmailDeliverer sends mail through an SMTP server
+func NewEmailDeliverer(cfg *analytics.SMTPConfig) *EmailDeliverer {
+	return &EmailDeliverer{smtp: cfg, send: cfg.SendMail}
+}
+
+// Deliver implements Deliverer
+func (e *EmailDeliverer) Deliver(_ context.Context, dest Destination, s *Schedule, r *Report, out *Rendered) error {
+	boundary, err := mimeBoundary()
+	if err != nil {
+		return err
+	}
+	subject := fmt.Sprintf("[Loom Report] %s", r.Title)
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.smtp.Sender())
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(dest.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/mixed; boundary=%q\r\n\r\n", boundary)
+
+	fmt.Fprintf(&msg, "--%s\r\n", boundary)
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(summaryText(r, s.Location()), "\n", "\r\n"))
+	fmt.Fprintf(&msg, "\r\nThe full report is attached as %s.\r\n", out.Filename)
+
+	fmt.Fprintf(&msg, "--%s\r\n", boundary)
+	fmt.Fprintf(&msg, "Content-Type: %s\r\n", out.ContentType)
+	msg.WriteString("Content-Transfer-Encoding: base64\r\n")
+	fmt.Fprintf(&msg, "Content-Disposition: attachment; filename=%q\r\n\r\n", out.Filename)
+	encoded := base64.StdEncoding.EncodeToString(out.Data)
+	for len(encoded) > 76 {
+		msg.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	msg.WriteString(encoded + "\r\n")
+	fmt.Fprintf(&msg, "--%s--\r\n", boundary)
+
+	return e.send(dest.To, msg.Bytes())
+}
+
+func mimeBoundary() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate MIME boundary: %w", err)
+	}
+	return "loom-" + hex.EncodeToString(b), nil
+}
+
+// NotificationDeliverer sends a report to Loom users as a notification, on
+// every channel their notification preferences enable. Markdown reports are
+// sent whole; other formats send their headline figures.
+type NotificationDeliverer struct {
+	send func(ctx context.Context, userID, title, message string) error
+}
+
+// NewNotificationDeliverer delivers through send, which notifies one user
+func NewNotificationDeliverer(send func(ctx context.Context, userID, title, message string) error) *NotificationDeliverer {
+	return &NotificationDeliverer{send: send}
+}
+
+// Deliver implements Deliverer
+func (nd *NotificationDeliverer) Deliver(ctx context.Context, dest Destination, s *Schedule, r *Report, out *Rendered) error {
+	message := summaryText(r, s.Location())
+	if s.Format == FormatMarkdown {
+		message = string(out.Data)
+	}
+	var failed []string
+	for _, userID := range dest.Users {
+		if err := nd.send(ctx, userID, r.Title, message); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", userID, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to notify %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// SlackDeliverer posts a report's summary and first table to a Slack
+// incoming webhook. Incoming webhooks cannot carry files, so the rendered
+// format does not apply.
+type SlackDeliverer struct {
+	client *http.Client
+}
+
+// NewSlackDeliverer creates a Slack deliverer
+func NewSlackDeliverer() *SlackDeliverer {
+	return &SlackDeliverer{client: &http.Client{Timeout: deliveryTimeout}}
+}
+
+// slackTableRows is how many rows of the first table are posted
+const slackTableRows = 10
+
+// Deliver implements Deliverer
+func (sd *SlackDeliverer) Deliver(ctx context.Context, dest Destination, s *Schedule, r *Report, _ *Rendered) error {
+	loc := s.Location()
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n_%s_\n", r.Title, r.Period(loc))
+	for _, m := range r.Summary {
+		fmt.Fprintf(&b, "• %s: *%s*\n", m.Label, m.Value)
+	}
+	if len(r.Tables) > 0 && len(r.Tables[0].Rows) > 0 {
+		t := r.Tables[0]
+		rows := t.Rows
+		if len(rows) > slackTableRows {
+			rows = rows[:slackTableRows]
+		}
+		widths := columnWidths(Table{Columns: t.Columns, Rows: rows})
+		fmt.Fprintf(&b, "\n*%s*\n```\n%s\n", t.Title, tableRow(t.Columns, widths))
+		for _, row := range rows {
+			b.WriteString(tableRow(row, widths) + "\n")
+		}
+		b.WriteString("```")
+		if len(t.Rows) > len(rows) {
+			fmt.Fprintf(&b, "\n_and %d more rows_", len(t.Rows)-len(rows))
+		}
+	}
+
+	body, err := json.Marshal(map[string]string{"text": b.String()})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dest.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := sd.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("slack returned %s", resp.Status)
+	}
+	return nil
+}
+
+// S3Deliverer uploads reports to S3 or an S3-compatible store, signing
+// requests with AWS Signature Version 4
+type S3Deliverer struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+	region          string
+	client          *http.Client
+	now             func() time.Time
+}
+
+// NewS3DelivererFromEnv reads credentials from AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, and the default region from
+// AWS_REGION. It returns nil when no credentials are set.
+func NewS3DelivererFromEnv() *S3Deliverer {
+	id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if id == "" || secret == "" {
+		return nil
+	}
+	return NewS3Deliverer(id, secret, os.Getenv("AWS_SESSION_TOKEN"), os.Getenv("AWS_REGION"))
+}
+
+// NewS3Deliverer creates an S3 deliverer with static credentials
+func NewS3Deliverer(accessKeyID, secretAccessKey, sessionToken, region string) *S3Deliverer {
+	if region == "" {
+		region = "us-east-1"
+	}
+	return &S3Deliverer{
+		accessKeyID:     accessKeyID,
+		secretAccessKey: secretAccessKey,
+		sessionToken:    sessionToken,
+		region:          region,
+		client:          &http.Client{Timeout: deliveryTimeout},
+		now:             time.Now,
+	}
+}
+
+// ObjectKey returns the key a report is stored under:
+// <prefix>/<org>/<file name>
+func ObjectKey(dest Destination, s *Schedule, out *Rendered) string {
+	parts := []string{}
+	if p := strings.Trim(dest.Prefix, "/"); p != "" {
+		parts = append(parts, p)
+	}
+	return strings.Join(append(parts, s.OrgID, out.Filename), "/")
+}
+
+// Deliver implements Deliverer
+func (d *S3Deliverer) Deliver(ctx context.Context, dest Destination, s *Schedule, _ *Report, out *Rendered) error {
+	return d.Put(ctx, dest, ObjectKey(dest, s, out), out.ContentType, out.Data)
+}
+
+// Put uploads data under key to the bucket of dest
+func (d *S3Deliverer) Put(ctx context.Context, dest Destination, key, contentType string, data []byte) error {
+	region := dest.Region
+	if region == "" {
+		region = d.region
+	}
+
+	var target string
+	if dest.Endpoint != "" {
+		target = strings.TrimRight(dest.Endpoint, "/") + "/" + dest.Bucket + "/" + awsEscapePath(key)
+	} else {
+		target = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", dest.Bucket, region, awsEscapePath(key))
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid s3 endpoint: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	d.sign(req, data, region, d.now().UTC())
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("s3 returned %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
+
+// sign adds SigV4 headers to an S3 request
+func (d *S3Deliverer) sign(req *http.Request, body []byte, region string, t time.Time) {
+	payloadHash := sha256Hex(body)
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if d.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", d.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+	signature := hex.EncodeToString(hmacSHA256(signingKey(d.secretAccessKey, date, region, "s3"), stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		d.accessKeyID, scope, signedHeaders, signature))
+}
+
+// signingKey derives the SigV4 key for a day, region and service
+func signingKey(secret, date, region, service string) []byte {
+	k := hmacSHA256([]byte("AWS4"+secret), date)
+	k = hmacSHA256(k, region)
+	k = hmacSHA256(k, service)
+	return hmacSHA256(k, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// awsEscapePath escapes each segment of an object key as SigV4 expects:
+// everything but unreserved characters is percent-encoded
+func awsEscapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c == '/', c == '-', c == '_', c == '.', c == '~',
+			'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
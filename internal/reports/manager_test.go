@@ -0,0 +1,167 @@
+package reports_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/org"
+	"github.com/jordanhubbard/loom/internal/reports"
+)
+
+// fakeDeliverer records deliveries and fails when err is set
+type fakeDeliverer struct {
+	err       error
+	delivered []*reports.Rendered
+}
+
+func (f *fakeDeliverer) Deliver(_ context.Context, _ reports.Destination, _ *reports.Schedule, _ *reports.Report, out *reports.Rendered) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.delivered = append(f.delivered, out)
+	return nil
+}
+
+func newTestManager(t *testing.T) (*reports.Manager, *database.Database, *fakeDeliverer, *fakeDeliverer) {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "reports.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m := reports.NewManager(db, reports.DefaultConfig())
+	m.RegisterGenerator(reports.ReportCost, func(_ context.Context, req reports.Request) (*reports.Report, error) {
+		return &reports.Report{
+			Title:   "Cost Report",
+			Summary: []reports.Metric{{Label: "Total cost", Value: "$1.00"}},
+			Tables:  []reports.Table{{Title: "By project", Columns: []string{"Project", "Cost"}, Rows: [][]string{{"p1", "1.00"}}}},
+		}, nil
+	})
+	email, slack := &fakeDeliverer{}, &fakeDeliverer{}
+	m.RegisterDeliverer(reports.DestinationEmail, email)
+	m.RegisterDeliverer(reports.DestinationSlack, slack)
+	return m, db, email, slack
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestManager_CreateValidates(t *testing.T) {
+	m, _, _, _ := newTestManager(t)
+	email := []reports.Destination{{Type: reports.DestinationEmail, To: []string{"ops@example.com"}}}
+	cases := map[string]reports.ScheduleRequest{
+		"unknown report": {ReportType: strPtr("nope"), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Destinations: &email},
+		"bad format":     {ReportType: strPtr(reports.ReportCost), Format: strPtr("docx"), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Destinations: &email},
+		"bad time":       {ReportType: strPtr(reports.ReportCost), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("25:00"), Destinations: &email},
+		"bad timezone":   {ReportType: strPtr(reports.ReportCost), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Timezone: strPtr("Mars/Olympus"), Destinations: &email},
+		"no destination": {ReportType: strPtr(reports.ReportCost), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00")},
+	}
+	for name, req := range cases {
+		if _, err := m.Create(req, ""); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	s, err := m.Create(reports.ScheduleRequest{ReportType: strPtr(reports.ReportCost), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Destinations: &email}, "admin")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if s.OrgID != org.DefaultID || s.Format != reports.FormatPDF || s.Timezone != "UTC" || !s.Enabled || s.NextRunAt == nil {
+		t.Errorf("expected defaults to be applied, got %+v", s)
+	}
+}
+
+func TestManager_RedactsAndRestoresSlackWebhook(t *testing.T) {
+	m, db, _, _ := newTestManager(t)
+	hook := "https://hooks.slack.com/services/T000/B000/secret"
+	dests := []reports.Destination{{Type: reports.DestinationSlack, WebhookURL: hook}}
+	s, err := m.Create(reports.ScheduleRequest{ReportType: strPtr(reports.ReportCost), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Destinations: &dests}, "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if strings.Contains(s.Destinations[0].WebhookURL, "secret") {
+		t.Errorf("expected webhook path to be redacted, got %s", s.Destinations[0].WebhookURL)
+	}
+
+	// Saving back what Get returned keeps the real URL
+	got, _ := m.Get(s.ID)
+	if _, err := m.Update(s.ID, reports.ScheduleRequest{Destinations: &got.Destinations}); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	stored, _ := db.GetReportSchedule(s.ID)
+	if stored.Destinations[0].WebhookURL != hook {
+		t.Errorf("expected webhook URL to be kept, got %s", stored.Destinations[0].WebhookURL)
+	}
+}
+
+func TestManager_RunNowRecordsPartialDelivery(t *testing.T) {
+	m, _, email, slack := newTestManager(t)
+	slack.err = errors.New("slack returned 500")
+	dests := []reports.Destination{
+		{Type: reports.DestinationEmail, To: []string{"ops@example.com"}},
+		{Type: reports.DestinationSlack, WebhookURL: "https://hooks.slack.com/services/x"},
+		{Type: reports.DestinationS3, Bucket: "reports"},
+	}
+	s, err := m.Create(reports.ScheduleRequest{
+		OrgID: strPtr("acme"), ReportType: strPtr(reports.ReportCost), Format: strPtr(reports.FormatCSV),
+		Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Destinations: &dests,
+	}, "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	run, err := m.RunNow(context.Background(), s.ID)
+	if err != nil {
+		t.Fatalf("RunNow failed: %v", err)
+	}
+	if run.Status != reports.RunPartial || run.Trigger != reports.TriggerManual || len(run.Destinations) != 3 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	if run.Destinations[0].Error != "" || run.Destinations[1].Error == "" ||
+		!strings.Contains(run.Destinations[2].Error, "not configured") {
+		t.Errorf("unexpected destination results: %+v", run.Destinations)
+	}
+	if len(email.delivered) != 1 || !strings.HasSuffix(email.delivered[0].Filename, ".csv") {
+		t.Errorf("expected one CSV to be mailed, got %+v", email.delivered)
+	}
+
+	runs, total, err := m.Runs(reports.RunFilter{ScheduleID: s.ID})
+	if err != nil || total != 1 || runs[0].ID != run.ID {
+		t.Errorf("expected the run to be logged, got %+v (%d, %v)", runs, total, err)
+	}
+	got, _ := m.Get(s.ID)
+	if got.LastStatus != reports.RunPartial || got.LastRunAt == nil {
+		t.Errorf("expected schedule to record the last run, got %+v", got)
+	}
+}
+
+func TestManager_RunDueAdvancesSchedule(t *testing.T) {
+	m, _, email, _ := newTestManager(t)
+	dests := []reports.Destination{{Type: reports.DestinationEmail, To: []string{"ops@example.com"}}}
+	s, err := m.Create(reports.ScheduleRequest{ReportType: strPtr(reports.ReportCost), Frequency: strPtr(reports.FrequencyDaily), At: strPtr("09:00"), Destinations: &dests}, "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	m.RunDue(context.Background(), s.NextRunAt.Add(-time.Minute))
+	if len(email.delivered) != 0 {
+		t.Fatal("expected nothing to run before the schedule is due")
+	}
+	m.RunDue(context.Background(), s.NextRunAt.Add(time.Minute))
+	if len(email.delivered) != 1 {
+		t.Fatalf("expected one delivery once due, got %d", len(email.delivered))
+	}
+	got, _ := m.Get(s.ID)
+	if got.NextRunAt == nil || !got.NextRunAt.After(time.Now()) {
+		t.Errorf("expected the next run to move into the future, got %v", got.NextRunAt)
+	}
+
+	runs, _, _ := m.Runs(reports.RunFilter{ScheduleID: s.ID})
+	if len(runs) != 1 || runs[0].Trigger != reports.TriggerSchedule || !runs[0].PeriodEnd.Equal(*s.NextRunAt) {
+		t.Errorf("expected a scheduled run ending at the due time, got %+v", runs)
+	}
+}
@@ -0,0 +1,191 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A minimal PDF writer: text only, in the standard fonts every viewer
+// ships, on A4 pages. Tables are set in Courier so columns line up.
+const (
+	pdfPageWidth  = 595
+	pdfPageHeight = 842
+	pdfMargin     = 50
+	pdfTableSize  = 9
+	// Courier advances 0.6em per character, so 91 characters fit a line
+	pdfTableChars = (pdfPageWidth - 2*pdfMargin) * 10 / (6 * pdfTableSize)
+)
+
+// Fonts, by resource name
+var pdfFonts = []struct{ name, base string }{
+	{"F1", "Helvetica"},
+	{"F2", "Helvetica-Bold"},
+	{"F3", "Courier"},
+	{"F4", "Courier-Bold"},
+}
+
+// pdfLine is one line of text and the vertical space it takes
+type pdfLine struct {
+	font    string
+	size    float64
+	leading float64
+	text    string
+}
+
+// renderPDF lays a report out as lines and writes them to pages
+func renderPDF(r *Report, loc *time.Location) []byte {
+	lines := []pdfLine{
+		{"F2", 16, 24, r.Title},
+		{"F1", 10, 14, r.Period(loc)},
+		{"F1", 10, 20, "Organization: " + r.OrgID},
+	}
+	if len(r.Summary) > 0 {
+		lines = append(lines, pdfLine{"F2", 12, 18, "Summary"})
+		width := 0
+		for _, m := range r.Summary {
+			width = max(width, len(m.Label))
+		}
+		for _, m := range r.Summary {
+			lines = append(lines, pdfLine{"F3", pdfTableSize, 12, fmt.Sprintf("%-*s  %s", width, m.Label, m.Value)})
+		}
+		lines = append(lines, pdfLine{"F1", 10, 10, ""})
+	}
+	for _, t := range r.Tables {
+		lines = append(lines, pdfLine{"F2", 12, 18, t.Title})
+		if len(t.Rows) == 0 {
+			lines = append(lines, pdfLine{"F1", 10, 14, "No data for this period."}, pdfLine{"F1", 10, 10, ""})
+			continue
+		}
+		widths := columnWidths(t)
+		lines = append(lines, pdfLine{"F4", pdfTableSize, 12, tableRow(t.Columns, widths)})
+		for _, row := range t.Rows {
+			lines = append(lines, pdfLine{"F3", pdfTableSize, 12, tableRow(row, widths)})
+		}
+		lines = append(lines, pdfLine{"F1", 10, 10, ""})
+	}
+	lines = append(lines, pdfLine{"F1", 8, 12, "Generated by Loom at " + r.GeneratedAt.In(loc).Format("2006-01-02 15:04 MST")})
+
+	// Break lines into pages
+	var pages []string
+	var page bytes.Buffer
+	y := float64(pdfPageHeight - pdfMargin)
+	for _, l := range lines {
+		if y-l.leading < pdfMargin && page.Len() > 0 {
+			pages = append(pages, page.String())
+			page.Reset()
+			y = pdfPageHeight - pdfMargin
+		}
+		y -= l.leading
+		if l.text != "" {
+			fmt.Fprintf(&page, "BT /%s %g Tf %d %.1f Td (%s) Tj ET\n", l.font, l.size, pdfMargin, y, pdfEscape(l.text))
+		}
+	}
+	pages = append(pages, page.String())
+	return writePDF(pages)
+}
+
+// columnWidths fits a table's columns into a page, narrowing the widest
+// column until the row fits
+func columnWidths(t Table) []int {
+	widths := make([]int, len(t.Columns))
+	for i, c := range t.Columns {
+		widths[i] = len(c)
+	}
+	for _, row := range t.Rows {
+		for i := 0; i < len(row) && i < len(widths); i++ {
+			widths[i] = max(widths[i], len(row[i]))
+		}
+	}
+	for {
+		total := 2 * (len(widths) - 1)
+		widest := 0
+		for i, w := range widths {
+			total += w
+			if w > widths[widest] {
+				widest = i
+			}
+		}
+		if total <= pdfTableChars || widths[widest] <= 4 {
+			return widths
+		}
+		widths[widest]--
+	}
+}
+
+// tableRow pads or truncates cells to their column widths
+func tableRow(cells []string, widths []int) string {
+	parts := make([]string, len(widths))
+	for i, w := range widths {
+		cell := ""
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		if len(cell) > w {
+			cell = cell[:w-1] + "~"
+		}
+		parts[i] = fmt.Sprintf("%-*s", w, cell)
+	}
+	return strings.TrimRight(strings.Join(parts, "  "), " ")
+}
+
+// pdfEscape makes text safe inside a PDF string. Only printable ASCII is
+// passed through, the one range every encoding of the standard fonts shares.
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '…':
+			b.WriteString("...")
+		case r < 32 || r > 126:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writePDF writes a document whose pages have the given content streams
+func writePDF(pages []string) []byte {
+	// Objects 1 and 2 are the catalog and page tree, followed by the fonts,
+	// then a content stream and a page for each page
+	fontObj := 3
+	firstPage := fontObj + len(pdfFonts)
+	objects := make([]string, firstPage-1+2*len(pages))
+
+	var fonts, kids strings.Builder
+	for i, f := range pdfFonts {
+		objects[fontObj-1+i] = fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.base)
+		fmt.Fprintf(&fonts, "/%s %d 0 R ", f.name, fontObj+i)
+	}
+	for i, content := range pages {
+		contentObj := firstPage + 2*i
+		pageObj := contentObj + 1
+		objects[contentObj-1] = fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content)
+		objects[pageObj-1] = fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << %s>> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, fonts.String(), contentObj)
+		fmt.Fprintf(&kids, "%d 0 R ", pageObj)
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.TrimSpace(kids.String()), len(pages))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,206 @@
+package reports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Report is a generated report: a few headline figures followed by tables
+type Report struct {
+	Title       string    `json:"title"`
+	OrgID       string    `json:"org_id"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	GeneratedAt time.Time `json:"generated_at"`
+	Summary     []Metric  `json:"summary,omitempty"`
+	Tables      []Table   `json:"tables,omitempty"`
+}
+
+// Metric is one headline figure
+type Metric struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Table is a titled table of preformatted cells
+type Table struct {
+	Title   string     `json:"title"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// Rendered is a report rendered to a file
+type Rendered struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// Render renders a report in a format, showing times in loc
+func Render(r *Report, format string, loc *time.Location) (*Rendered, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	base := fmt.Sprintf("%s-%s", slug(r.Title), r.PeriodEnd.In(loc).Format("2006-01-02-1504"))
+	switch format {
+	case FormatCSV:
+		data, err := renderCSV(r, loc)
+		if err != nil {
+			return nil, err
+		}
+		return &Rendered{Filename: base + ".csv", ContentType: "text/csv", Data: data}, nil
+	case FormatHTML:
+		data, err := renderHTML(r, loc)
+		if err != nil {
+			return nil, err
+		}
+		return &Rendered{Filename: base + ".html", ContentType: "text/html; charset=UTF-8", Data: data}, nil
+	case FormatPDF:
+		return &Rendered{Filename: base + ".pdf", ContentType: "application/pdf", Data: renderPDF(r, loc)}, nil
+	case FormatMarkdown:
+		return &Rendered{Filename: base + ".md", ContentType: "text/markdown; charset=UTF-8", Data: renderMarkdown(r, loc)}, nil
+	default:
+		return nil, fmt.Errorf("invalid format %q", format)
+	}
+}
+
+// Period describes the report's period in loc
+func (r *Report) Period(loc *time.Location) string {
+	const layout = "2006-01-02 15:04 MST"
+	return r.PeriodStart.In(loc).Format(layout) + " to " + r.PeriodEnd.In(loc).Format(layout)
+}
+
+var slugRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns a title into a file name
+func slug(title string) string {
+	s := strings.Trim(slugRe.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if s == "" {
+		return "report"
+	}
+	return s
+}
+
+// renderCSV writes the summary and each table as sections separated by a
+// blank row, the layout the analytics statistics export uses
+func renderCSV(r *Report, loc *time.Location) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{r.Title})
+	_ = w.Write([]string{"Period", r.Period(loc)})
+	_ = w.Write([]string{"Organization", r.OrgID})
+	_ = w.Write(nil)
+
+	if len(r.Summary) > 0 {
+		_ = w.Write([]string{"Summary"})
+		_ = w.Write([]string{"Metric", "Value"})
+		for _, m := range r.Summary {
+			_ = w.Write([]string{m.Label, m.Value})
+		}
+		_ = w.Write(nil)
+	}
+	for _, t := range r.Tables {
+		_ = w.Write([]string{t.Title})
+		_ = w.Write(t.Columns)
+		for _, row := range t.Rows {
+			_ = w.Write(row)
+		}
+		_ = w.Write(nil)
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="UTF-8">
+<title>{{.Report.Title}}</title>
+<style>
+body { font-family: Arial, sans-serif; color: #333; max-width: 960px; margin: 0 auto; padding: 20px; }
+h1 { color: #1f2937; margin-bottom: 4px; }
+.period { color: #6b7280; margin-top: 0; }
+.summary { display: flex; flex-wrap: wrap; gap: 12px; margin: 20px 0; }
+.metric { background: #f3f4f6; border-radius: 6px; padding: 10px 14px; }
+.metric .label { font-size: 12px; color: #6b7280; }
+.metric .value { font-size: 18px; font-weight: bold; }
+table { border-collapse: collapse; width: 100%; margin-bottom: 24px; }
+th, td { border: 1px solid #e5e7eb; padding: 6px 8px; text-align: left; font-size: 13px; }
+th { background: #f9fafb; }
+</style>
+</head>
+<body>
+<h1>{{.Report.Title}}</h1>
+<p class="period">{{.Period}} &middot; {{.Report.OrgID}}</p>
+{{if .Report.Summary}}<div class="summary">{{range .Report.Summary}}
+<div class="metric"><div class="label">{{.Label}}</div><div class="value">{{.Value}}</div></div>{{end}}
+</div>{{end}}
+{{range .Report.Tables}}<h2>{{.Title}}</h2>
+{{if .Rows}}<table>
+<tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>{{else}}<p>No data for this period.</p>{{end}}
+{{end}}<p class="period">Generated by Loom at {{.Generated}}</p>
+</body>
+</html>
+`))
+
+// renderHTML renders a standalone HTML page
+func renderHTML(r *Report, loc *time.Location) ([]byte, error) {
+	var buf bytes.Buffer
+	err := htmlTemplate.Execute(&buf, map[string]interface{}{
+		"Report":    r,
+		"Period":    r.Period(loc),
+		"Generated": r.GeneratedAt.In(loc).Format("2006-01-02 15:04 MST"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render report: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// renderMarkdown renders the summary as a list and each table as a
+// GitHub-flavored markdown table
+func renderMarkdown(r *Report, loc *time.Location) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_%s · %s_\n", r.Title, r.Period(loc), r.OrgID)
+	if len(r.Summary) > 0 {
+		b.WriteString("\n")
+		for _, m := range r.Summary {
+			fmt.Fprintf(&b, "- **%s:** %s\n", m.Label, markdownCell(m.Value))
+		}
+	}
+	for _, t := range r.Tables {
+		fmt.Fprintf(&b, "\n## %s\n\n", t.Title)
+		if len(t.Rows) == 0 {
+			b.WriteString("No data for this period.\n")
+			continue
+		}
+		b.WriteString(markdownRow(t.Columns))
+		b.WriteString("|" + strings.Repeat(" --- |", len(t.Columns)) + "\n")
+		for _, row := range t.Rows {
+			b.WriteString(markdownRow(row))
+		}
+	}
+	fmt.Fprintf(&b, "\n_Generated by Loom at %s_\n", r.GeneratedAt.In(loc).Format("2006-01-02 15:04 MST"))
+	return []byte(b.String())
+}
+
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, c := range cells {
+		escaped[i] = markdownCell(c)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
+
+// markdownCell keeps a value on one line and out of the table syntax
+func markdownCell(v string) string {
+	v = strings.ReplaceAll(v, "|", "\\|")
+	return strings.Join(strings.Fields(v), " ")
+}
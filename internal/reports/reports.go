@@ -0,0 +1,788 @@
+// Package reports generates reports on a schedule and delivers them to
+// destinations outside Loom. A schedule belongs to an organization, names
+// the report and its format, and fires at a wall-clock time in its own time
+// zone; each run covers the period since the previous occurrence and is
+// sent to every destination (email, Slack, an S3 bucket or Loom users'
+// notification channels) and logged.
+package reports
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jordanhubbard/loom/internal/org"
+)
+
+// Report types generated by Loom
+const (
+	ReportCost           = "cost"
+	ReportPromptAnalysis = "prompt_analysis"
+	ReportProjectHealth  = "project_health"
+	ReportGoldenPrompts  = "golden_prompts"
+	ReportStandup        = "standup"
+)
+
+// Output formats
+const (
+	FormatPDF      = "pdf"
+	FormatHTML     = "html"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+// Schedule frequencies
+const (
+	FrequencyDaily   = "daily"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// Destination types
+const (
+	DestinationEmail        = "email"
+	DestinationSlack        = "slack"
+	DestinationS3           = "s3"
+	DestinationNotification = "notification"
+)
+
+// Run statuses
+const (
+	RunSucceeded = "succeeded" // Delivered to every destination
+	RunPartial   = "partial"   // Delivered to some destinations
+	RunFailed    = "failed"    // Not generated, or delivered nowhere
+)
+
+// Run triggers
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Destination is where a report is sent
+type Destination struct {
+	Type       string   `json:"type"`                  // email, slack, s3 or notification
+	To         []string `json:"to,omitempty"`          // Email recipients
+	Users      []string `json:"users,omitempty"`       // Loom users notified through their notification channels
+	WebhookURL string   `json:"webhook_url,omitempty"` // Slack incoming webhook; only its host is shown after saving
+	Bucket     string   `json:"bucket,omitempty"`      // S3 bucket
+	Prefix     string   `json:"prefix,omitempty"`      // S3 key prefix
+	Region     string   `json:"region,omitempty"`      // S3 region, default AWS_REGION or us-east-1
+	Endpoint   string   `json:"endpoint,omitempty"`    // S3-compatible endpoint; path-style addressing is used when set
+}
+
+// Schedule delivers one report on a recurring schedule
+type Schedule struct {
+	ID           string            `json:"id"`
+	OrgID        string            `json:"org_id"`
+	Name         string            `json:"name"`
+	ReportType   string            `json:"report_type"`
+	Format       string            `json:"format"`
+	Params       map[string]string `json:"params,omitempty"` // Report options, e.g. project_id
+	Frequency    string            `json:"frequency"`
+	At           string            `json:"at"`                     // Local time of day, HH:MM
+	Weekday      int               `json:"weekday,omitempty"`      // Weekly: 0 (Sunday) to 6
+	DayOfMonth   int               `json:"day_of_month,omitempty"` // Monthly: 1 to 31, clamped to the month's last day
+	Timezone     string            `json:"timezone"`               // IANA name, e.g. Europe/Berlin
+	Destinations []Destination     `json:"destinations"`
+	Enabled      bool              `json:"enabled"`
+	NextRunAt    *time.Time        `json:"next_run_at,omitempty"`
+	LastRunAt    *time.Time        `json:"last_run_at,omitempty"`
+	LastStatus   string            `json:"last_status,omitempty"`
+	CreatedBy    string            `json:"created_by,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// ScheduleRequest creates or updates a schedule. On update, nil fields are
+// left unchanged.
+type ScheduleRequest struct {
+	OrgID        *string            `json:"org_id,omitempty"` // Defaults to "default" on create
+	Name         *string            `json:"name,omitempty"`
+	ReportType   *string            `json:"report_type,omitempty"`
+	Format       *string            `json:"format,omitempty"` // Defaults to pdf on create
+	Params       *map[string]string `json:"params,omitempty"`
+	Frequency    *string            `json:"frequency,omitempty"`
+	At           *string            `json:"at,omitempty"`
+	Weekday      *int               `json:"weekday,omitempty"`
+	DayOfMonth   *int               `json:"day_of_month,omitempty"`
+	Timezone     *string            `json:"timezone,omitempty"` // Defaults to UTC on create
+	Destinations *[]Destination     `json:"destinations,omitempty"`
+	Enabled      *bool              `json:"enabled,omitempty"` // Defaults to true on create
+}
+
+// DestinationResult is the outcome of delivering a run to one destination
+type DestinationResult struct {
+	Type   string `json:"type"`
+	Target string `json:"target"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Run is one generation and delivery of a scheduled report
+type Run struct {
+	ID           string              `json:"id"`
+	ScheduleID   string              `json:"schedule_id"`
+	OrgID        string              `json:"org_id"`
+	ReportType   string              `json:"report_type"`
+	Format       string              `json:"format"`
+	Trigger      string              `json:"trigger"`
+	Status       string              `json:"status"`
+	PeriodStart  time.Time           `json:"period_start"`
+	PeriodEnd    time.Time           `json:"period_end"`
+	Filename     string              `json:"filename,omitempty"`
+	SizeBytes    int                 `json:"size_bytes,omitempty"`
+	Destinations []DestinationResult `json:"destinations,omitempty"`
+	Error        string              `json:"error,omitempty"`
+	StartedAt    time.Time           `json:"started_at"`
+	FinishedAt   time.Time           `json:"finished_at"`
+}
+
+// RunFilter selects runs from the log, newest first unless Ascending is set
+type RunFilter struct {
+	ScheduleID string
+	Status     string
+	Ascending  bool
+	Limit      int // 0 means no limit
+	Offset     int
+	After      []interface{} // (started_at, id) of the last run of the previous page
+}
+
+// Store persists schedules and the run log
+type Store interface {
+	// SaveReportSchedule inserts or replaces a schedule
+	SaveReportSchedule(s *Schedule) error
+	// GetReportSchedule returns a schedule, or nil if unknown
+	GetReportSchedule(id string) (*Schedule, error)
+	// ListReportSchedules returns the schedules of an organization, or of
+	// every organization when orgID is empty
+	ListReportSchedules(orgID string) ([]*Schedule, error)
+	// DeleteReportSchedule removes a schedule and its run log
+	DeleteReportSchedule(id string) error
+	// SaveReportRun inserts or replaces a run
+	SaveReportRun(r *Run) error
+	// ListReportRuns returns a page of runs and the total matching f
+	ListReportRuns(f RunFilter) ([]*Run, int, error)
+}
+
+// Request is what a generator is asked to report on
+type Request struct {
+	OrgID    string
+	Params   map[string]string
+	Start    time.Time
+	End      time.Time
+	Location *time.Location // The schedule's time zone, for rendering times
+}
+
+// Generator builds a report
+type Generator func(ctx context.Context, req Request) (*Report, error)
+
+// Deliverer sends a rendered report to one destination
+type Deliverer interface {
+	Deliver(ctx context.Context, dest Destination, s *Schedule, report *Report, out *Rendered) error
+}
+
+// Config tunes the scheduler
+type Config struct {
+	PollInterval time.Duration // How often due schedules are looked for
+}
+
+// DefaultConfig checks for due schedules every minute
+func DefaultConfig() Config {
+	return Config{PollInterval: time.Minute}
+}
+
+// Manager owns the schedules and runs them when due. A nil Manager
+// schedules nothing.
+type Manager struct {
+	store Store
+	cfg   Config
+
+	mu         sync.RWMutex
+	generators map[string]Generator
+	deliverers map[string]Deliverer
+	running    map[string]bool // Schedules with a run in progress
+	stop       chan struct{}
+}
+
+// NewManager creates a manager backed by store
+func NewManager(store Store, cfg Config) *Manager {
+	if store == nil {
+		return nil
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = DefaultConfig().PollInterval
+	}
+	return &Manager{
+		store:      store,
+		cfg:        cfg,
+		generators: make(map[string]Generator),
+		deliverers: make(map[string]Deliverer),
+		running:    make(map[string]bool),
+	}
+}
+
+// RegisterGenerator registers the generator of a report type
+func (m *Manager) RegisterGenerator(reportType string, g Generator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.generators[reportType] = g
+}
+
+// RegisterDeliverer registers the deliverer of a destination type
+func (m *Manager) RegisterDeliverer(destType string, d Deliverer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliverers[destType] = d
+}
+
+// ReportTypes returns the report types that can be scheduled
+func (m *Manager) ReportTypes() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	types := make([]string, 0, len(m.generators))
+	for t := range m.generators {
+		types = append(types, t)
+	}
+	return types
+}
+
+// Location returns the schedule's time zone
+func (s *Schedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// NextRun returns the first occurrence of the schedule after t
+func (s *Schedule) NextRun(t time.Time) time.Time {
+	loc := s.Location()
+	hour, minute := clock(s.At)
+	local := t.In(loc)
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	// A monthly schedule recurs within 31 days, a weekly one within 7; the
+	// bound only guards against a malformed schedule
+	for i := 0; i <= 62; i++ {
+		d := day.AddDate(0, 0, i)
+		if !s.onDay(d) {
+			continue
+		}
+		at := time.Date(d.Year(), d.Month(), d.Day(), hour, minute, 0, 0, loc)
+		if at.After(t) {
+			return at
+		}
+	}
+	return t.Add(24 * time.Hour)
+}
+
+// Period returns the span a run at t reports on: the time since the
+// previous occurrence, one day, week or month before t in the schedule's
+// time zone
+func (s *Schedule) Period(t time.Time) (time.Time, time.Time) {
+	local := t.In(s.Location())
+	switch s.Frequency {
+	case FrequencyWeekly:
+		return local.AddDate(0, 0, -7), t
+	case FrequencyMonthly:
+		return local.AddDate(0, -1, 0), t
+	default:
+		return local.AddDate(0, 0, -1), t
+	}
+}
+
+// onDay reports whether the schedule fires on the day starting at d
+func (s *Schedule) onDay(d time.Time) bool {
+	switch s.Frequency {
+	case FrequencyWeekly:
+		return int(d.Weekday()) == s.Weekday
+	case FrequencyMonthly:
+		lastDay := time.Date(d.Year(), d.Month()+1, 0, 0, 0, 0, 0, d.Location()).Day()
+		want := s.DayOfMonth
+		if want > lastDay {
+			want = lastDay
+		}
+		return d.Day() == want
+	default:
+		return true
+	}
+}
+
+// clock parses a validated HH:MM time of day
+func clock(at string) (int, int) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return 0, 0
+	}
+	return t.Hour(), t.Minute()
+}
+
+// validate checks the fields a schedule needs to run
+func (s *Schedule) validate(generators map[string]Generator) error {
+	if s.OrgID == "" {
+		return fmt.Errorf("org_id must not be empty")
+	}
+	if _, ok := generators[s.ReportType]; !ok {
+		return fmt.Errorf("invalid report_type %q", s.ReportType)
+	}
+	switch s.Format {
+	case FormatPDF, FormatHTML, FormatCSV, FormatMarkdown:
+	default:
+		return fmt.Errorf("invalid format %q (expected pdf, html, csv or markdown)", s.Format)
+	}
+	switch s.Frequency {
+	case FrequencyDaily:
+	case FrequencyWeekly:
+		if s.Weekday < 0 || s.Weekday > 6 {
+			return fmt.Errorf("weekday must be between 0 (Sunday) and 6")
+		}
+	case FrequencyMonthly:
+		if s.DayOfMonth < 1 || s.DayOfMonth > 31 {
+			return fmt.Errorf("day_of_month must be between 1 and 31")
+		}
+	default:
+		return fmt.Errorf("invalid frequency %q (expected daily, weekly or monthly)", s.Frequency)
+	}
+	if _, err := time.Parse("15:04", s.At); err != nil {
+		return fmt.Errorf("invalid at %q (expected HH:MM)", s.At)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone %q", s.Timezone)
+	}
+	if len(s.Destinations) == 0 {
+		return fmt.Errorf("destinations must name at least one destination")
+	}
+	for i, d := range s.Destinations {
+		if err := d.validate(); err != nil {
+			return fmt.Errorf("destination %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// validate checks the fields a destination needs
+func (d *Destination) validate() error {
+	switch d.Type {
+	case DestinationEmail:
+		if len(d.To) == 0 {
+			return fmt.Errorf("email destinations must list recipients in to")
+		}
+		for _, addr := range d.To {
+			if !strings.Contains(addr, "@") || strings.ContainsAny(addr, "\r\n") {
+				return fmt.Errorf("invalid email address %q", addr)
+			}
+		}
+	case DestinationSlack:
+		if !strings.HasPrefix(d.WebhookURL, "https://") && !strings.HasPrefix(d.WebhookURL, "http://") {
+			return fmt.Errorf("slack destinations must set an http or https webhook_url")
+		}
+	case DestinationS3:
+		if d.Bucket == "" {
+			return fmt.Errorf("s3 destinations must set bucket")
+		}
+	case DestinationNotification:
+		if len(d.Users) == 0 {
+			return fmt.Errorf("notification destinations must list user IDs in users")
+		}
+	default:
+		return fmt.Errorf("invalid destination type %q (expected email, slack, s3 or notification)", d.Type)
+	}
+	return nil
+}
+
+// target describes a destination for the run log without its secrets
+func (d *Destination) target() string {
+	switch d.Type {
+	case DestinationEmail:
+		return strings.Join(d.To, ", ")
+	case DestinationSlack:
+		return redactURL(d.WebhookURL)
+	case DestinationS3:
+		return "s3://" + d.Bucket + "/" + strings.TrimPrefix(d.Prefix, "/")
+	case DestinationNotification:
+		return strings.Join(d.Users, ", ")
+	default:
+		return d.Type
+	}
+}
+
+// redactURL keeps the scheme and host of a URL whose path is a credential
+func redactURL(raw string) string {
+	if i := strings.Index(raw, "://"); i >= 0 {
+		if j := strings.Index(raw[i+3:], "/"); j >= 0 {
+			return raw[:i+3+j] + "/…"
+		}
+	}
+	return raw
+}
+
+// redacted returns a copy of s without its Slack webhook paths
+func (s *Schedule) redacted() *Schedule {
+	cp := *s
+	cp.Destinations = make([]Destination, len(s.Destinations))
+	for i, d := range s.Destinations {
+		if d.WebhookURL != "" {
+			d.WebhookURL = redactURL(d.WebhookURL)
+		}
+		cp.Destinations[i] = d
+	}
+	return &cp
+}
+
+// restoreWebhooks puts back Slack webhook URLs that were sent back in the
+// redacted form Get returns, so a schedule can be read, edited and saved
+func (s *Schedule) restoreWebhooks(previous []Destination) {
+	for i, d := range s.Destinations {
+		if d.WebhookURL == "" {
+			continue
+		}
+		for _, p := range previous {
+			if p.WebhookURL != "" && p.WebhookURL != d.WebhookURL && redactURL(p.WebhookURL) == d.WebhookURL {
+				s.Destinations[i].WebhookURL = p.WebhookURL
+				break
+			}
+		}
+	}
+}
+
+// apply copies the set fields of req onto s
+func (req *ScheduleRequest) apply(s *Schedule) {
+	if req.OrgID != nil {
+		s.OrgID = strings.TrimSpace(*req.OrgID)
+	}
+	if req.Name != nil {
+		s.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ReportType != nil {
+		s.ReportType = *req.ReportType
+	}
+	if req.Format != nil {
+		s.Format = strings.ToLower(*req.Format)
+	}
+	if req.Params != nil {
+		s.Params = *req.Params
+	}
+	if req.Frequency != nil {
+		s.Frequency = *req.Frequency
+	}
+	if req.At != nil {
+		s.At = *req.At
+	}
+	if req.Weekday != nil {
+		s.Weekday = *req.Weekday
+	}
+	if req.DayOfMonth != nil {
+		s.DayOfMonth = *req.DayOfMonth
+	}
+	if req.Timezone != nil {
+		s.Timezone = *req.Timezone
+	}
+	if req.Destinations != nil {
+		s.Destinations = *req.Destinations
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+}
+
+// Create adds a schedule
+func (m *Manager) Create(req ScheduleRequest, createdBy string) (*Schedule, error) {
+	now := time.Now().UTC()
+	s := &Schedule{
+		ID:        uuid.New().String(),
+		OrgID:     org.DefaultID,
+		Format:    FormatPDF,
+		Timezone:  "UTC",
+		Enabled:   true,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	req.apply(s)
+	if err := m.validate(s); err != nil {
+		return nil, err
+	}
+	if s.Name == "" {
+		s.Name = fmt.Sprintf("%s %s report", s.Frequency, s.ReportType)
+	}
+	s.reschedule(now)
+	if err := m.store.SaveReportSchedule(s); err != nil {
+		return nil, err
+	}
+	return s.redacted(), nil
+}
+
+// Update changes the fields set in req
+func (m *Manager) Update(id string, req ScheduleRequest) (*Schedule, error) {
+	s, err := m.schedule(id)
+	if err != nil {
+		return nil, err
+	}
+	previous := s.Destinations
+	req.apply(s)
+	s.restoreWebhooks(previous)
+	if err := m.validate(s); err != nil {
+		return nil, err
+	}
+	s.UpdatedAt = time.Now().UTC()
+	s.reschedule(s.UpdatedAt)
+	if err := m.store.SaveReportSchedule(s); err != nil {
+		return nil, err
+	}
+	return s.redacted(), nil
+}
+
+// Get returns a schedule
+func (m *Manager) Get(id string) (*Schedule, error) {
+	s, err := m.schedule(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.redacted(), nil
+}
+
+// List returns the schedules of an organization, or of every organization
+// when orgID is empty
+func (m *Manager) List(orgID string) ([]*Schedule, error) {
+	list, err := m.store.ListReportSchedules(orgID)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Schedule, 0, len(list))
+	for _, s := range list {
+		out = append(out, s.redacted())
+	}
+	return out, nil
+}
+
+// Delete removes a schedule and its run log
+func (m *Manager) Delete(id string) error {
+	if _, err := m.schedule(id); err != nil {
+		return err
+	}
+	return m.store.DeleteReportSchedule(id)
+}
+
+// Runs returns a page of a schedule's run log
+func (m *Manager) Runs(f RunFilter) ([]*Run, int, error) {
+	if _, err := m.schedule(f.ScheduleID); err != nil {
+		return nil, 0, err
+	}
+	return m.store.ListReportRuns(f)
+}
+
+func (m *Manager) schedule(id string) (*Schedule, error) {
+	s, err := m.store.GetReportSchedule(id)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return nil, fmt.Errorf("report schedule %s not found", id)
+	}
+	return s, nil
+}
+
+func (m *Manager) validate(s *Schedule) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return s.validate(m.generators)
+}
+
+// reschedule sets the next run after t, or clears it when disabled
+func (s *Schedule) reschedule(t time.Time) {
+	if !s.Enabled {
+		s.NextRunAt = nil
+		return
+	}
+	next := s.NextRun(t).UTC()
+	s.NextRunAt = &next
+}
+
+// RunNow generates and delivers a schedule's report for the period ending
+// now, whether or not it is enabled
+func (m *Manager) RunNow(ctx context.Context, id string) (*Run, error) {
+	s, err := m.schedule(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.run(ctx, s, time.Now(), TriggerManual)
+}
+
+// Start looks for due schedules until ctx is done or Close is called
+func (m *Manager) Start(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	if m.stop != nil {
+		m.mu.Unlock()
+		return
+	}
+	stop := make(chan struct{})
+	m.stop = stop
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.cfg.PollInterval)
+		defer ticker.Stop()
+		m.RunDue(ctx, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				m.RunDue(ctx, now)
+			}
+		}
+	}()
+}
+
+// Close stops looking for due schedules
+func (m *Manager) Close() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// RunDue runs every enabled schedule whose next run is at or before now.
+// A schedule that missed several occurrences while Loom was down runs once.
+func (m *Manager) RunDue(ctx context.Context, now time.Time) {
+	if m == nil {
+		return
+	}
+	list, err := m.store.ListReportSchedules("")
+	if err != nil {
+		log.Printf("[Reports] Failed to list schedules: %v", err)
+		return
+	}
+	for _, s := range list {
+		if !s.Enabled || s.NextRunAt == nil || s.NextRunAt.After(now) {
+			continue
+		}
+		if _, err := m.run(ctx, s, *s.NextRunAt, TriggerSchedule); err != nil {
+			log.Printf("[Reports] Schedule %s failed: %v", s.ID, err)
+		}
+	}
+}
+
+// run generates the report for the period ending at, delivers it and
+// records the run. Scheduled runs also advance the schedule.
+func (m *Manager) run(ctx context.Context, s *Schedule, at time.Time, trigger string) (*Run, error) {
+	m.mu.Lock()
+	if m.running[s.ID] {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("report schedule %s is already running", s.ID)
+	}
+	m.running[s.ID] = true
+	generate := m.generators[s.ReportType]
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.running, s.ID)
+		m.mu.Unlock()
+	}()
+
+	start, end := s.Period(at)
+	r := &Run{
+		ID:          uuid.New().String(),
+		ScheduleID:  s.ID,
+		OrgID:       s.OrgID,
+		ReportType:  s.ReportType,
+		Format:      s.Format,
+		Trigger:     trigger,
+		PeriodStart: start.UTC(),
+		PeriodEnd:   end.UTC(),
+		StartedAt:   time.Now().UTC(),
+	}
+	m.deliver(ctx, s, generate, r)
+	r.FinishedAt = time.Now().UTC()
+	if err := m.store.SaveReportRun(r); err != nil {
+		log.Printf("[Reports] Failed to record run %s: %v", r.ID, err)
+	}
+
+	// Reload so an update made while the report was generated is kept
+	cur, err := m.store.GetReportSchedule(s.ID)
+	if err != nil || cur == nil {
+		return r, nil
+	}
+	cur.LastRunAt = &r.FinishedAt
+	cur.LastStatus = r.Status
+	if trigger == TriggerSchedule {
+		cur.reschedule(time.Now())
+	}
+	if err := m.store.SaveReportSchedule(cur); err != nil {
+		log.Printf("[Reports] Failed to update schedule %s: %v", s.ID, err)
+	}
+	return r, nil
+}
+
+// deliver generates, renders and sends a run's report, filling in its
+// outcome
+func (m *Manager) deliver(ctx context.Context, s *Schedule, generate Generator, r *Run) {
+	if generate == nil {
+		r.Status, r.Error = RunFailed, fmt.Sprintf("no generator for report type %q", s.ReportType)
+		return
+	}
+	report, err := generate(ctx, Request{
+		OrgID:    s.OrgID,
+		Params:   s.Params,
+		Start:    r.PeriodStart,
+		End:      r.PeriodEnd,
+		Location: s.Location(),
+	})
+	if err != nil {
+		r.Status, r.Error = RunFailed, fmt.Sprintf("failed to generate report: %v", err)
+		return
+	}
+	if report.Title == "" {
+		report.Title = s.Name
+	}
+	report.OrgID = s.OrgID
+	report.PeriodStart, report.PeriodEnd = r.PeriodStart, r.PeriodEnd
+	if report.GeneratedAt.IsZero() {
+		report.GeneratedAt = time.Now().UTC()
+	}
+
+	out, err := Render(report, s.Format, s.Location())
+	if err != nil {
+		r.Status, r.Error = RunFailed, err.Error()
+		return
+	}
+	r.Filename, r.SizeBytes = out.Filename, len(out.Data)
+
+	delivered := 0
+	for _, dest := range s.Destinations {
+		result := DestinationResult{Type: dest.Type, Target: dest.target()}
+		m.mu.RLock()
+		d := m.deliverers[dest.Type]
+		m.mu.RUnlock()
+		if d == nil {
+			result.Error = fmt.Sprintf("%s delivery is not configured", dest.Type)
+		} else if err := d.Deliver(ctx, dest, s, report, out); err != nil {
+			result.Error = err.Error()
+		} else {
+			delivered++
+		}
+		r.Destinations = append(r.Destinations, result)
+	}
+	switch delivered {
+	case len(s.Destinations):
+		r.Status = RunSucceeded
+	case 0:
+		r.Status, r.Error = RunFailed, "delivery failed to every destination"
+	default:
+		r.Status, r.Error = RunPartial, "delivery failed to some destinations"
+	}
+}
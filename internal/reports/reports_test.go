@@ -0,0 +1,361 @@
+package reports
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/analytics"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("time zone %s not available: %v", name, err)
+	}
+	return loc
+}
+
+func TestNextRun_Daily_TimeZone(t *testing.T) {
+	berlin := mustLocation(t, "Europe/Berlin")
+	s := &Schedule{Frequency: FrequencyDaily, At: "09:00", Timezone: "Europe/Berlin"}
+
+	// 07:30 UTC is 09:30 in Berlin in summer, so today's run has passed
+	now := time.Date(2026, 7, 1, 7, 30, 0, 0, time.UTC)
+	want := time.Date(2026, 7, 2, 9, 0, 0, 0, berlin)
+	if got := s.NextRun(now); !got.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", got, want)
+	}
+
+	// 06:30 UTC is 08:30 in Berlin, so it runs today
+	now = time.Date(2026, 7, 1, 6, 30, 0, 0, time.UTC)
+	want = time.Date(2026, 7, 1, 9, 0, 0, 0, berlin)
+	if got := s.NextRun(now); !got.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", got, want)
+	}
+}
+
+func TestNextRun_KeepsLocalTimeAcrossDST(t *testing.T) {
+	ny := mustLocation(t, "America/New_York")
+	s := &Schedule{Frequency: FrequencyDaily, At: "08:00", Timezone: "America/New_York"}
+
+	// Clocks go forward on 2026-03-08; the run stays at 08:00 local
+	before := s.NextRun(time.Date(2026, 3, 7, 12, 0, 0, 0, time.UTC))
+	after := s.NextRun(before)
+	if before.In(ny).Hour() != 8 || after.In(ny).Hour() != 8 {
+		t.Errorf("expected 08:00 local on both days, got %v and %v", before.In(ny), after.In(ny))
+	}
+	if gap := after.Sub(before); gap != 23*time.Hour {
+		t.Errorf("expected a 23h gap across the DST change, got %v", gap)
+	}
+}
+
+func TestNextRun_Weekly(t *testing.T) {
+	s := &Schedule{Frequency: FrequencyWeekly, Weekday: int(time.Monday), At: "07:15", Timezone: "UTC"}
+	// 2026-10-15 is a Thursday
+	got := s.NextRun(time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC))
+	want := time.Date(2026, 10, 19, 7, 15, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", got, want)
+	}
+}
+
+func TestNextRun_MonthlyClampsToLastDay(t *testing.T) {
+	s := &Schedule{Frequency: FrequencyMonthly, DayOfMonth: 31, At: "00:30", Timezone: "UTC"}
+	got := s.NextRun(time.Date(2026, 2, 10, 0, 0, 0, 0, time.UTC))
+	want := time.Date(2026, 2, 28, 0, 30, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("NextRun = %v, want %v", got, want)
+	}
+	got = s.NextRun(want)
+	want = time.Date(2026, 3, 31, 0, 30, 0, 0, time.UTC)
+	if !got.Equal(want) {
+		t.Errorf("NextRun after February = %v, want %v", got, want)
+	}
+}
+
+func TestSchedulePeriod(t *testing.T) {
+	at := time.Date(2026, 3, 31, 9, 0, 0, 0, time.UTC)
+	s := &Schedule{Frequency: FrequencyMonthly, Timezone: "UTC"}
+	start, end := s.Period(at)
+	if !end.Equal(at) || !start.Equal(time.Date(2026, 3, 3, 9, 0, 0, 0, time.UTC)) {
+		// AddDate normalizes February 31st to March 3rd
+		t.Errorf("monthly period = %v to %v", start, end)
+	}
+	s.Frequency = FrequencyWeekly
+	if start, _ := s.Period(at); at.Sub(start) != 7*24*time.Hour {
+		t.Errorf("weekly period starts %v", start)
+	}
+}
+
+func testReport() *Report {
+	return &Report{
+		Title:       "Cost Report",
+		OrgID:       "acme",
+		PeriodStart: time.Date(2026, 10, 14, 9, 0, 0, 0, time.UTC),
+		PeriodEnd:   time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC),
+		GeneratedAt: time.Date(2026, 10, 15, 9, 0, 5, 0, time.UTC),
+		Summary:     []Metric{{Label: "Total cost", Value: "$12.50"}},
+		Tables: []Table{
+			{Title: "By project", Columns: []string{"Project", "Cost"}, Rows: [][]string{{"loom (core)", "12.50"}, {"<script>", "0.00"}}},
+			{Title: "Empty", Columns: []string{"A"}},
+		},
+	}
+}
+
+func TestRender_CSV(t *testing.T) {
+	out, err := Render(testReport(), FormatCSV, time.UTC)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if out.Filename != "cost-report-2026-10-15-0900.csv" || out.ContentType != "text/csv" {
+		t.Errorf("unexpected file: %s %s", out.Filename, out.ContentType)
+	}
+	r := csv.NewReader(bytes.NewReader(out.Data))
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	found := false
+	for _, rec := range records {
+		if len(rec) == 2 && rec[0] == "loom (core)" && rec[1] == "12.50" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected table row in CSV, got %v", records)
+	}
+}
+
+func TestRender_HTMLEscapes(t *testing.T) {
+	out, err := Render(testReport(), FormatHTML, mustLocation(t, "Asia/Tokyo"))
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	html := string(out.Data)
+	if strings.Contains(html, "<script>") || !strings.Contains(html, "&lt;script&gt;") {
+		t.Error("expected cell contents to be escaped")
+	}
+	if !strings.Contains(html, "2026-10-15 18:00 JST") {
+		t.Error("expected times in the schedule's time zone")
+	}
+	if !strings.Contains(html, "No data for this period.") {
+		t.Error("expected empty tables to be called out")
+	}
+}
+
+func TestRender_Markdown(t *testing.T) {
+	r := testReport()
+	r.Tables[0].Rows = append(r.Tables[0].Rows, []string{"a|b", "line\nbreak"})
+	out, err := Render(r, FormatMarkdown, time.UTC)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	if out.Filename != "cost-report-2026-10-15-0900.md" {
+		t.Errorf("unexpected filename %s", out.Filename)
+	}
+	md := string(out.Data)
+	for _, want := range []string{
+		"# Cost Report",
+		"- **Total cost:** $12.50",
+		"| Project | Cost |",
+		"| loom (core) | 12.50 |",
+		"| a\\|b | line break |",
+		"No data for this period.",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("expected %q in markdown, got:\n%s", want, md)
+		}
+	}
+}
+
+func TestRender_PDF(t *testing.T) {
+	r := testReport()
+	// Enough rows to need a second page
+	for i := 0; i < 80; i++ {
+		r.Tables[0].Rows = append(r.Tables[0].Rows, []string{"project (with parens)", "1.00"})
+	}
+	out, err := Render(r, FormatPDF, time.UTC)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	data := string(out.Data)
+	if !strings.HasPrefix(data, "%PDF-") || !strings.HasSuffix(data, "%%EOF\n") {
+		t.Error("expected a complete PDF document")
+	}
+	if !strings.Contains(data, "/Count 2") {
+		t.Error("expected the table to flow onto a second page")
+	}
+	if !strings.Contains(data, `project \(with parens\)`) {
+		t.Error("expected parentheses to be escaped")
+	}
+}
+
+func TestColumnWidthsFitPage(t *testing.T) {
+	widths := columnWidths(Table{
+		Columns: []string{"Type", "Recommendation"},
+		Rows:    [][]string{{"verbosity", strings.Repeat("x", 300)}},
+	})
+	total := widths[0] + widths[1] + 2
+	if total > pdfTableChars {
+		t.Errorf("expected row to fit %d characters, got %d", pdfTableChars, total)
+	}
+	if widths[0] != len("verbosity") {
+		t.Errorf("expected narrow column to keep its width, got %d", widths[0])
+	}
+}
+
+func TestEmailDeliverer_BuildsAttachment(t *testing.T) {
+	var to []string
+	var raw []byte
+	e := NewEmailDeliverer(&analytics.SMTPConfig{Host: "smtp.example.com", From: "loom@example.com"})
+	e.send = func(rcpt []string, msg []byte) error {
+		to, raw = rcpt, msg
+		return nil
+	}
+	out := &Rendered{Filename: "cost-report.pdf", ContentType: "application/pdf", Data: []byte("%PDF-1.4 test")}
+	dest := Destination{Type: DestinationEmail, To: []string{"a@example.com", "b@example.com"}}
+	if err := e.Deliver(context.Background(), dest, &Schedule{Timezone: "UTC"}, testReport(), out); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if len(to) != 2 {
+		t.Errorf("expected both recipients, got %v", to)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	if msg.Header.Get("From") != "loom@example.com" {
+		t.Errorf("unexpected sender %q", msg.Header.Get("From"))
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/mixed" {
+		t.Fatalf("expected a multipart message, got %q (%v)", mediaType, err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	text, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("missing body part: %v", err)
+	}
+	body, _ := io.ReadAll(text)
+	if !strings.Contains(string(body), "Total cost: $12.50") {
+		t.Errorf("expected the summary in the body, got %q", body)
+	}
+	attachment, err := mr.NextPart()
+	if err != nil {
+		t.Fatalf("missing attachment: %v", err)
+	}
+	if attachment.FileName() != "cost-report.pdf" {
+		t.Errorf("unexpected attachment name %q", attachment.FileName())
+	}
+	data, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	if string(data) != "%PDF-1.4 test" {
+		t.Errorf("attachment did not round-trip, got %q", data)
+	}
+}
+
+func TestSigningKey_AWSExample(t *testing.T) {
+	// From the AWS Signature Version 4 documentation
+	key := signingKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	want := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("signingKey = %s, want %s", got, want)
+	}
+}
+
+func TestS3Deliverer_UploadsSigned(t *testing.T) {
+	var gotPath, gotAuth, gotType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth, gotType = r.URL.EscapedPath(), r.Header.Get("Authorization"), r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	d := NewS3Deliverer("AKID", "secret", "", "eu-west-1")
+	d.now = func() time.Time { return time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC) }
+	dest := Destination{Type: DestinationS3, Bucket: "reports", Prefix: "/loom/", Endpoint: srv.URL}
+	out := &Rendered{Filename: "cost report.csv", ContentType: "text/csv", Data: []byte("a,b\n")}
+	if err := d.Deliver(context.Background(), dest, &Schedule{OrgID: "acme"}, testReport(), out); err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+
+	if gotPath != "/reports/loom/acme/cost%20report.csv" {
+		t.Errorf("unexpected object path %s", gotPath)
+	}
+	if !strings.HasPrefix(gotAuth, "AWS4-HMAC-SHA256 Credential=AKID/20261015/eu-west-1/s3/aws4_request, SignedHeaders=") ||
+		!strings.Contains(gotAuth, "host;x-amz-content-sha256;x-amz-date") {
+		t.Errorf("unexpected Authorization header %q", gotAuth)
+	}
+	if gotType != "text/csv" || string(gotBody) != "a,b\n" {
+		t.Errorf("unexpected upload %s %q", gotType, gotBody)
+	}
+}
+
+func TestS3Deliverer_ReportsErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<Error><Code>AccessDenied</Code></Error>", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	d := NewS3Deliverer("AKID", "secret", "", "")
+	err := d.Deliver(context.Background(), Destination{Bucket: "b", Endpoint: srv.URL}, &Schedule{OrgID: "o"}, testReport(),
+		&Rendered{Filename: "r.pdf", ContentType: "application/pdf"})
+	if err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected the S3 error to be returned, got %v", err)
+	}
+}
+
+func TestSlackDeliverer_PostsSummary(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	err := NewSlackDeliverer().Deliver(context.Background(), Destination{WebhookURL: srv.URL}, &Schedule{Timezone: "UTC"}, testReport(), nil)
+	if err != nil {
+		t.Fatalf("Deliver failed: %v", err)
+	}
+	if !strings.Contains(string(body), "Total cost") || !strings.Contains(string(body), "loom (core)") {
+		t.Errorf("expected summary and table in the message, got %s", body)
+	}
+}
+
+func TestNotificationDeliverer_SendsEachUser(t *testing.T) {
+	sent := map[string]string{}
+	d := NewNotificationDeliverer(func(ctx context.Context, userID, title, message string) error {
+		if userID == "bob" {
+			return io.ErrUnexpectedEOF
+		}
+		sent[userID] = message
+		return nil
+	})
+	r := testReport()
+	out, err := Render(r, FormatMarkdown, time.UTC)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	err = d.Deliver(context.Background(), Destination{Users: []string{"alice", "bob"}}, &Schedule{Timezone: "UTC", Format: FormatMarkdown}, r, out)
+	if err == nil || !strings.Contains(err.Error(), "bob") {
+		t.Errorf("expected the failed user to be reported, got %v", err)
+	}
+	if sent["alice"] != string(out.Data) {
+		t.Errorf("expected the markdown report to be sent whole, got %q", sent["alice"])
+	}
+}
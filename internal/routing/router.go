@@ -8,6 +8,7 @@ import (
 	"time"
 
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/pkg/models"
 )
 
 // RoutingPolicy defines how providers should be selected
@@ -30,6 +31,34 @@ type ProviderRequirements struct {
 	RequiredTags     []string // Provider must have these tags
 }
 
+// WithCompliance returns requirements that also demand the provider tags a
+// project's compliance constraints require. The receiver is not modified.
+func (req *ProviderRequirements) WithCompliance(c *models.ComplianceConstraints) *ProviderRequirements {
+	if c == nil || len(c.RequiredProviderTags) == 0 {
+		return req
+	}
+	merged := ProviderRequirements{}
+	if req != nil {
+		merged = *req
+	}
+	merged.RequiredTags = append([]string{}, merged.RequiredTags...)
+	for _, tag := range c.RequiredProviderTags {
+		if !containsTag(merged.RequiredTags, tag) {
+			merged.RequiredTags = append(merged.RequiredTags, tag)
+		}
+	}
+	return &merged
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
 // Router selects optimal providers based on policies and requirements
 type Router struct {
 	policy RoutingPolicy
@@ -255,7 +284,7 @@ func (r *Router) scoreByQuality(p *internalmodels.Provider) float64 {
 
 	// Provider type bonus (some types generally higher quality)
 	switch p.Type {
-	case "openai":
+	case "openai", "azure":
 		score += 10.0
 	case "anthropic":
 		score += 10.0
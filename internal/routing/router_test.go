@@ -6,6 +6,7 @@ import (
 	"time"
 
 	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/pkg/models"
 )
 
 func TestSelectProvider_MinimizeCost(t *testing.T) {
@@ -343,3 +344,29 @@ func TestIsHealthy(t *testing.T) {
 		})
 	}
 }
+
+func TestWithCompliance(t *testing.T) {
+	providers := []*internalmodels.Provider{
+		{ID: "us", Status: "active", Tags: []string{"us-hosted"}},
+		{ID: "eu", Status: "active", Tags: []string{"eu-hosted"}, CostPerMToken: 5},
+	}
+	c := &models.ComplianceConstraints{RequiredProviderTags: []string{"eu-hosted"}}
+
+	base := &ProviderRequirements{MaxCostPerMToken: 10}
+	req := base.WithCompliance(c)
+	if len(base.RequiredTags) != 0 {
+		t.Error("WithCompliance must not modify the receiver")
+	}
+	if req.MaxCostPerMToken != 10 || len(req.RequiredTags) != 1 {
+		t.Errorf("unexpected merged requirements: %+v", req)
+	}
+
+	var none *ProviderRequirements
+	selected, err := NewRouter(PolicyMinimizeCost).SelectProvider(context.Background(), providers, none.WithCompliance(c))
+	if err != nil {
+		t.Fatalf("SelectProvider failed: %v", err)
+	}
+	if selected.ID != "eu" {
+		t.Errorf("expected the eu-hosted provider, got %s", selected.ID)
+	}
+}
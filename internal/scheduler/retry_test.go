@@ -0,0 +1,22 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	def := ActivityOptions{}
+	if d := def.retryDelay(1); d != time.Second {
+		t.Errorf("first default delay = %v", d)
+	}
+	if d := def.retryDelay(10); d != time.Minute {
+		t.Errorf("default delays should stop growing at a minute: %v", d)
+	}
+	opts := ActivityOptions{InitialInterval: 100 * time.Millisecond, BackoffCoefficient: 3, MaximumInterval: time.Second}
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 300 * time.Millisecond, 3: 900 * time.Millisecond, 4: time.Second} {
+		if d := opts.retryDelay(attempt); d != want {
+			t.Errorf("delay after attempt %d = %v, want %v", attempt, d, want)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+// Package scheduler runs Loom's recurring workflows without Temporal. Each
+// workflow is a durable timer kept in the database. When a timer is due, an
+// instance leases it, runs the timer's activity and moves the timer to its
+// next firing. A lease whose holder died expires and is taken over, so every
+// firing runs its activity at least once, and possibly more than once.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DefaultPollInterval is how often due timers are looked for
+const DefaultPollInterval = time.Second
+
+// leaseMargin is how long a lease outlasts its activity's timeout, so a
+// slow finish is not mistaken for a dead holder
+const leaseMargin = 30 * time.Second
+
+// Retries back off exponentially by default, from a second to a minute
+const (
+	defaultRetryDelay    = time.Second
+	defaultMaxRetryDelay = time.Minute
+)
+
+// Timer is a durable timer. When FireAt passes, its activity runs with
+// Input. A recurring timer then fires again Interval after the activity
+// finishes; a one-shot timer is removed.
+type Timer struct {
+	ID         string          `json:"id"`
+	Activity   string          `json:"activity"`
+	Input      json.RawMessage `json:"input,omitempty"`
+	Interval   time.Duration   `json:"interval"` // 0 fires once
+	FireAt     time.Time       `json:"fire_at"`
+	Runs       int64           `json:"runs"`     // Firings handled, successful or not
+	Attempts   int             `json:"attempts"` // Failed attempts at the current firing
+	LeaseOwner string          `json:"lease_owner,omitempty"`
+	LeaseUntil *time.Time      `json:"lease_until,omitempty"`
+	LastRunAt  *time.Time      `json:"last_run_at,omitempty"`
+	LastError  string          `json:"last_error,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// leased reports whether a live lease holds t at now
+func (t *Timer) leased(now time.Time) bool {
+	return t.LeaseOwner != "" && t.LeaseUntil != nil && t.LeaseUntil.After(now)
+}
+
+// Store persists timers. Claim and finish must be atomic, as several
+// instances may share one database.
+type Store interface {
+	// SaveSchedulerTimer inserts or replaces a timer
+	SaveSchedulerTimer(t *Timer) error
+	// GetSchedulerTimer returns a timer, or nil if unknown
+	GetSchedulerTimer(id string) (*Timer, error)
+	// ListSchedulerTimers returns every timer, soonest first
+	ListSchedulerTimers() ([]*Timer, error)
+	// DeleteSchedulerTimer removes a timer
+	DeleteSchedulerTimer(id string) error
+	// ClaimSchedulerTimer leases a timer that is due at now and not held by
+	// a live lease to owner until until, and reports whether it did
+	ClaimSchedulerTimer(id, owner string, now, until time.Time) (bool, error)
+	// FinishSchedulerTimer saves a leased timer's next firing and releases
+	// its lease if owner still holds it, and reports whether it did
+	FinishSchedulerTimer(t *Timer, owner string) (bool, error)
+}
+
+// Activity is the work done each time a timer fires. run numbers the
+// firings of the timer from 1.
+type Activity func(ctx context.Context, input json.RawMessage, run int64) error
+
+// ActivityOptions bound an activity as Temporal's activity options do
+type ActivityOptions struct {
+	Timeout            time.Duration // Per attempt; default 10 minutes
+	MaxAttempts        int           // Attempts per firing; default 1
+	InitialInterval    time.Duration // Delay before the first retry; default 1s
+	BackoffCoefficient float64       // Growth of each later delay; default 2
+	MaximumInterval    time.Duration // Longest delay; default 1 minute
+}
+
+type activity struct {
+	fn   Activity
+	opts ActivityOptions
+}
+
+// Scheduler fires timers. Its methods do nothing on a nil Scheduler.
+type Scheduler struct {
+	store Store
+	owner string
+	poll  time.Duration
+
+	mu         sync.Mutex
+	activities map[string]activity
+	running    map[string]bool // Timers this instance is running
+	triggered  map[string]bool // Running timers to fire again at once
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// New creates a scheduler keeping its timers in store, which looks for due
+// timers every poll
+func New(store Store, poll time.Duration) *Scheduler {
+	if store == nil {
+		return nil
+	}
+	if poll <= 0 {
+		poll = DefaultPollInterval
+	}
+	host, _ := os.Hostname()
+	return &Scheduler{
+		store:      store,
+		owner:      fmt.Sprintf("%s-%d-%s", host, os.Getpid(), uuid.New().String()[:8]),
+		poll:       poll,
+		activities: make(map[string]activity),
+		running:    make(map[string]bool),
+		triggered:  make(map[string]bool),
+	}
+}
+
+// Owner identifies this instance in leases
+func (s *Scheduler) Owner() string {
+	if s == nil {
+		return ""
+	}
+	return s.owner
+}
+
+// Register names the activity timers run. Timers whose activity is not
+// registered on an instance are left for another.
+func (s *Scheduler) Register(name string, fn Activity, opts ActivityOptions) {
+	if s == nil {
+		return
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activities[name] = activity{fn: fn, opts: opts}
+}
+
+// Schedule creates the timer id, first firing after delay. Like starting a
+// Temporal workflow whose ID is already running, scheduling an existing
+// timer keeps its next firing and run count; only its activity, input and
+// interval are updated.
+func (s *Scheduler) Schedule(id, activityName string, input interface{}, interval, delay time.Duration) error {
+	if s == nil {
+		return nil
+	}
+	var raw json.RawMessage
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return fmt.Errorf("failed to encode input of timer %s: %w", id, err)
+		}
+		raw = data
+	}
+
+	now := time.Now().UTC()
+	t, err := s.store.GetSchedulerTimer(id)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		t = &Timer{ID: id, FireAt: now.Add(delay), CreatedAt: now}
+	} else if t.Activity == activityName && t.Interval == interval && string(t.Input) == string(raw) {
+		return nil
+	}
+	t.Activity = activityName
+	t.Input = raw
+	t.Interval = interval
+	t.UpdatedAt = now
+	return s.store.SaveSchedulerTimer(t)
+}
+
+// Trigger fires a timer now rather than at its next firing, as a signal
+// wakes a waiting Temporal workflow
+func (s *Scheduler) Trigger(id string) error {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	if s.running[id] {
+		s.triggered[id] = true
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	t, err := s.store.GetSchedulerTimer(id)
+	if err != nil {
+		return err
+	}
+	if t == nil {
+		return fmt.Errorf("timer not found: %s", id)
+	}
+	now := time.Now().UTC()
+	if t.leased(now) {
+		return nil // Already firing elsewhere
+	}
+	t.FireAt = now
+	t.UpdatedAt = now
+	return s.store.SaveSchedulerTimer(t)
+}
+
+// Cancel removes a timer. A firing already under way finishes.
+func (s *Scheduler) Cancel(id string) error {
+	if s == nil {
+		return nil
+	}
+	return s.store.DeleteSchedulerTimer(id)
+}
+
+// Timers returns every timer, soonest first
+func (s *Scheduler) Timers() ([]*Timer, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return s.store.ListSchedulerTimers()
+}
+
+// Start looks for due timers every poll interval until ctx ends or Close
+func (s *Scheduler) Start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
+
+	log.Printf("[Scheduler] Embedded scheduler started as %s (poll every %s)", s.owner, s.poll)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.poll)
+		defer ticker.Stop()
+		for {
+			s.fireDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Close stops looking for timers and waits for running activities, whose
+// context is cancelled
+func (s *Scheduler) Close() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+}
+
+// RunDue fires every due timer and waits for them, returning how many fired
+func (s *Scheduler) RunDue(ctx context.Context) int {
+	if s == nil {
+		return 0
+	}
+	var wg sync.WaitGroup
+	n := s.fire(ctx, &wg)
+	wg.Wait()
+	return n
+}
+
+// fireDue starts every due timer without waiting for them
+func (s *Scheduler) fireDue(ctx context.Context) {
+	s.fire(ctx, &s.wg)
+}
+
+func (s *Scheduler) fire(ctx context.Context, wg *sync.WaitGroup) int {
+	timers, err := s.store.ListSchedulerTimers()
+	if err != nil {
+		log.Printf("[Scheduler] Failed to list timers: %v", err)
+		return 0
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].FireAt.Before(timers[j].FireAt) })
+
+	fired := 0
+	now := time.Now().UTC()
+	for _, t := range timers {
+		if t.FireAt.After(now) || t.leased(now) {
+			continue
+		}
+		s.mu.Lock()
+		act, ok := s.activities[t.Activity]
+		busy := s.running[t.ID]
+		if ok && !busy {
+			s.running[t.ID] = true
+		}
+		s.mu.Unlock()
+		if !ok || busy {
+			continue
+		}
+
+		claimed, err := s.store.ClaimSchedulerTimer(t.ID, s.owner, now, now.Add(act.opts.Timeout+leaseMargin))
+		if err != nil || !claimed {
+			if err != nil {
+				log.Printf("[Scheduler] Failed to claim timer %s: %v", t.ID, err)
+			}
+			s.done(t.ID)
+			continue
+		}
+		fired++
+		wg.Add(1)
+		go func(t *Timer) {
+			defer wg.Done()
+			defer s.done(t.ID)
+			s.run(ctx, t, act)
+		}(t)
+	}
+	return fired
+}
+
+func (s *Scheduler) done(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.running, id)
+	delete(s.triggered, id)
+}
+
+// run performs one attempt at a timer's firing and records the outcome
+func (s *Scheduler) run(ctx context.Context, t *Timer, act activity) {
+	runCtx, cancel := context.WithTimeout(ctx, act.opts.Timeout)
+	err := safeCall(runCtx, act.fn, t.Input, t.Runs+1)
+	cancel()
+	if ctx.Err() != nil {
+		// Shutting down: leave the lease to expire so the firing is retried
+		return
+	}
+
+	now := time.Now().UTC()
+	t.LeaseOwner, t.LeaseUntil = "", nil
+	t.LastRunAt = &now
+	t.UpdatedAt = now
+	if err != nil {
+		t.Attempts++
+		t.LastError = err.Error()
+		if t.Attempts < act.opts.MaxAttempts {
+			t.FireAt = now.Add(act.opts.retryDelay(t.Attempts))
+			log.Printf("[Scheduler] %s attempt %d failed, retrying: %v", t.ID, t.Attempts, err)
+			s.finish(t)
+			return
+		}
+		log.Printf("[Scheduler] %s failed after %d attempts: %v", t.ID, t.Attempts, err)
+	} else {
+		t.LastError = ""
+	}
+	t.Attempts = 0
+	t.Runs++
+
+	if t.Interval <= 0 {
+		if err := s.store.DeleteSchedulerTimer(t.ID); err != nil {
+			log.Printf("[Scheduler] Failed to remove timer %s: %v", t.ID, err)
+		}
+		return
+	}
+	t.FireAt = now.Add(t.Interval)
+	s.mu.Lock()
+	if s.triggered[t.ID] {
+		t.FireAt = now
+	}
+	s.mu.Unlock()
+	s.finish(t)
+}
+
+func (s *Scheduler) finish(t *Timer) {
+	ok, err := s.store.FinishSchedulerTimer(t, s.owner)
+	switch {
+	case err != nil:
+		log.Printf("[Scheduler] Failed to save timer %s: %v", t.ID, err)
+	case !ok:
+		log.Printf("[Scheduler] Lost the lease on timer %s before it finished", t.ID)
+	}
+}
+
+// safeCall runs an activity, turning a panic into an error
+func safeCall(ctx context.Context, fn Activity, input json.RawMessage, run int64) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("activity panicked: %v", r)
+		}
+	}()
+	return fn(ctx, input, run)
+}
+
+// retryDelay is how long to wait after the given failed attempt
+func (o ActivityOptions) retryDelay(attempt int) time.Duration {
+	initial, coefficient, max := o.InitialInterval, o.BackoffCoefficient, o.MaximumInterval
+	if initial <= 0 {
+		initial = defaultRetryDelay
+	}
+	if coefficient < 1 {
+		coefficient = 2
+	}
+	if max <= 0 {
+		max = defaultMaxRetryDelay
+	}
+	d := float64(initial) * math.Pow(coefficient, float64(attempt-1))
+	if d > float64(max) {
+		return max
+	}
+	return time.Duration(d)
+}
+
+// Execute runs fn now, retrying it as an activity with opts would be. It
+// is for workflows a caller waits on, which need no timer.
+func Execute(ctx context.Context, opts ActivityOptions, fn func(ctx context.Context) error) error {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Minute
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	var err error
+	for attempt := 1; attempt <= opts.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(opts.retryDelay(attempt - 1)):
+			}
+		}
+		runCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
+		err = fn(runCtx)
+		cancel()
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package scheduler_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/scheduler"
+)
+
+func newTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "scheduler.db"))
+	if err != nil {
+		t.Fatalf("database.New failed: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSchedulerFiresRecurringTimer(t *testing.T) {
+	store := newTestDB(t)
+	s := scheduler.New(store, time.Hour)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var runs []int64
+	var inputs []string
+	s.Register("beat", func(_ context.Context, input json.RawMessage, run int64) error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, run)
+		inputs = append(inputs, string(input))
+		return nil
+	}, scheduler.ActivityOptions{})
+
+	if err := s.Schedule("heartbeat", "beat", map[string]string{"p": "1"}, time.Hour, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.RunDue(ctx); n != 1 {
+		t.Fatalf("RunDue fired %d timers, want 1", n)
+	}
+	if n := s.RunDue(ctx); n != 0 {
+		t.Fatalf("a timer fired again before its interval: %d", n)
+	}
+
+	// Scheduling again keeps the next firing and run count
+	if err := s.Schedule("heartbeat", "beat", map[string]string{"p": "1"}, time.Hour, 0); err != nil {
+		t.Fatal(err)
+	}
+	timer, _ := store.GetSchedulerTimer("heartbeat")
+	if timer.Runs != 1 || time.Until(timer.FireAt) < 59*time.Minute || timer.LeaseOwner != "" {
+		t.Fatalf("timer after a firing = %+v", timer)
+	}
+
+	if err := s.Trigger("heartbeat"); err != nil {
+		t.Fatal(err)
+	}
+	s.RunDue(ctx)
+	if len(runs) != 2 || runs[1] != 2 || inputs[0] != `{"p":"1"}` {
+		t.Errorf("runs = %v, inputs = %v", runs, inputs)
+	}
+
+	// A new scheduler, as after a restart, continues the count
+	s2 := scheduler.New(store, time.Hour)
+	s2.Register("beat", func(_ context.Context, _ json.RawMessage, run int64) error {
+		runs = append(runs, run)
+		return nil
+	}, scheduler.ActivityOptions{})
+	s2.Trigger("heartbeat")
+	s2.RunDue(ctx)
+	if runs[len(runs)-1] != 3 {
+		t.Errorf("run after restart = %d, want 3", runs[len(runs)-1])
+	}
+}
+
+func TestSchedulerRetriesAndGivesUp(t *testing.T) {
+	store := newTestDB(t)
+	s := scheduler.New(store, time.Hour)
+	ctx := context.Background()
+
+	calls := 0
+	s.Register("flaky", func(context.Context, json.RawMessage, int64) error {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		return errors.New("still failing")
+	}, scheduler.ActivityOptions{MaxAttempts: 2})
+	if err := s.Schedule("job", "flaky", nil, time.Hour, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	s.RunDue(ctx)
+	timer, _ := store.GetSchedulerTimer("job")
+	if timer.Attempts != 1 || timer.Runs != 0 || timer.LastError == "" || time.Until(timer.FireAt) > 2*time.Second {
+		t.Fatalf("after the first failure: %+v", timer)
+	}
+
+	// Retry when the backoff passes
+	timer.FireAt = time.Now().Add(-time.Millisecond)
+	store.SaveSchedulerTimer(timer)
+	s.RunDue(ctx)
+	timer, _ = store.GetSchedulerTimer("job")
+	if calls != 2 || timer.Attempts != 0 || timer.Runs != 1 || timer.LastError != "still failing" || time.Until(timer.FireAt) < 59*time.Minute {
+		t.Fatalf("after giving up: calls %d, %+v", calls, timer)
+	}
+}
+
+func TestSchedulerLeases(t *testing.T) {
+	store := newTestDB(t)
+	ctx := context.Background()
+	a, b := scheduler.New(store, time.Hour), scheduler.New(store, time.Hour)
+
+	ran := map[string]int{}
+	for _, s := range []*scheduler.Scheduler{a, b} {
+		s := s
+		s.Register("once", func(context.Context, json.RawMessage, int64) error {
+			ran[s.Owner()]++
+			return nil
+		}, scheduler.ActivityOptions{Timeout: time.Minute})
+	}
+	if err := a.Schedule("one-shot", "once", nil, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance holds a live lease: nothing runs
+	now := time.Now().UTC()
+	if ok, _ := store.ClaimSchedulerTimer("one-shot", "dead-instance", now, now.Add(time.Hour)); !ok {
+		t.Fatal("claim failed")
+	}
+	if n := a.RunDue(ctx) + b.RunDue(ctx); n != 0 {
+		t.Fatalf("a leased timer fired %d times", n)
+	}
+
+	// The holder died: once its lease expires, one instance runs the firing
+	timer, _ := store.GetSchedulerTimer("one-shot")
+	past := now.Add(-time.Second)
+	timer.LeaseUntil = &past
+	store.SaveSchedulerTimer(timer)
+	if n := a.RunDue(ctx) + b.RunDue(ctx); n != 1 {
+		t.Fatalf("the expired lease was taken over %d times, want 1", n)
+	}
+	if ran[a.Owner()]+ran[b.Owner()] != 1 {
+		t.Errorf("ran = %v", ran)
+	}
+	if timer, _ := store.GetSchedulerTimer("one-shot"); timer != nil {
+		t.Errorf("a finished one-shot timer should be removed: %+v", timer)
+	}
+}
+
+func TestSchedulerSkipsUnregisteredActivity(t *testing.T) {
+	store := newTestDB(t)
+	s := scheduler.New(store, time.Hour)
+	if err := s.Schedule("other", "elsewhere", nil, time.Minute, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n := s.RunDue(context.Background()); n != 0 {
+		t.Errorf("an unregistered activity fired %d times", n)
+	}
+	if timer, _ := store.GetSchedulerTimer("other"); timer.LeaseOwner != "" {
+		t.Error("an instance without the activity should not lease the timer")
+	}
+}
+
+func TestExecute(t *testing.T) {
+	calls := 0
+	err := scheduler.Execute(context.Background(), scheduler.ActivityOptions{MaxAttempts: 2}, func(context.Context) error {
+		calls++
+		if calls < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || calls != 2 {
+		t.Errorf("Execute = %v after %d calls", err, calls)
+	}
+}
@@ -0,0 +1,201 @@
+// Package search indexes the activity feed and beads for full-text search,
+// powering the global search box. Activities are indexed by the database
+// as they are recorded. Beads live outside the database, so the manager
+// indexes them, together with their comments, whenever they change.
+package search
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Kinds of indexed documents
+const (
+	KindActivity = "activity"
+	KindBead     = "bead"
+)
+
+// Search limits
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+	maxTerms     = 10
+)
+
+// Document is an activity or bead as it is indexed
+type Document struct {
+	Kind       string
+	ID         string
+	OrgID      string
+	ProjectID  string
+	ActorID    string
+	Title      string
+	Body       string
+	OccurredAt time.Time
+}
+
+// Query selects search results. Every term must match, as a word or the
+// start of one, in the title or body of a document.
+type Query struct {
+	Terms     []string
+	Kind      string // activity or bead; empty for both
+	OrgID     string // Only this organization's documents; empty for all
+	ProjectID string
+	ActorID   string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// Result is a matching document, best matches first
+type Result struct {
+	Kind       string    `json:"kind"`
+	ID         string    `json:"id"`
+	ProjectID  string    `json:"project_id,omitempty"`
+	ActorID    string    `json:"actor_id,omitempty"`
+	Title      string    `json:"title"`
+	Snippet    string    `json:"snippet,omitempty"` // Matching text, with matches in [brackets]
+	OccurredAt time.Time `json:"occurred_at"`
+	Score      float64   `json:"score"`
+}
+
+// Store persists the index
+type Store interface {
+	// SaveSearchDocument inserts or replaces a document
+	SaveSearchDocument(d *Document) error
+	// DeleteSearchDocument removes a document if it is indexed
+	DeleteSearchDocument(kind, id string) error
+	// SearchDocuments returns the documents matching q, best first
+	SearchDocuments(q Query) ([]*Result, error)
+}
+
+// Beads reads the beads to index
+type Beads interface {
+	// BeadDocument returns a bead and its comments as a document, or nil
+	// when the bead no longer exists
+	BeadDocument(beadID string) (*Document, error)
+}
+
+// Manager searches the index and keeps the beads in it current
+type Manager struct {
+	store Store
+	beads Beads
+
+	mu      sync.Mutex
+	pending map[string]bool // Beads waiting to be indexed
+	wake    chan struct{}
+}
+
+// NewManager returns a manager over store. Call Start to index the beads
+// passed to BeadChanged.
+func NewManager(store Store, beads Beads) *Manager {
+	return &Manager{
+		store:   store,
+		beads:   beads,
+		pending: make(map[string]bool),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// BeadChanged queues a bead to be indexed again. It never blocks, so it
+// may be called while the caller holds its own locks.
+func (m *Manager) BeadChanged(beadID string) {
+	if m == nil || beadID == "" {
+		return
+	}
+	m.mu.Lock()
+	m.pending[beadID] = true
+	m.mu.Unlock()
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start indexes queued beads in the background until ctx is done
+func (m *Manager) Start(ctx context.Context) {
+	if m == nil {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-m.wake:
+				m.IndexPending()
+			}
+		}
+	}()
+}
+
+// IndexPending indexes the queued beads
+func (m *Manager) IndexPending() {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.pending))
+	for id := range m.pending {
+		ids = append(ids, id)
+	}
+	m.pending = make(map[string]bool)
+	m.mu.Unlock()
+
+	for _, id := range ids {
+		if err := m.indexBead(id); err != nil {
+			log.Printf("[Search] Failed to index bead %s: %v", id, err)
+		}
+	}
+}
+
+// indexBead writes a bead to the index. Saving replaces the bead's
+// document, so indexing an unchanged bead again is harmless.
+func (m *Manager) indexBead(beadID string) error {
+	doc, err := m.beads.BeadDocument(beadID)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return m.store.DeleteSearchDocument(KindBead, beadID)
+	}
+	doc.Kind, doc.ID = KindBead, beadID
+	return m.store.SaveSearchDocument(doc)
+}
+
+// Search returns the documents matching text and the filters of q, best
+// matches first
+func (m *Manager) Search(text string, q Query) ([]*Result, error) {
+	if m == nil {
+		return nil, fmt.Errorf("search not available")
+	}
+	q.Terms = Terms(text)
+	if len(q.Terms) == 0 {
+		return nil, fmt.Errorf("query has no words to search for")
+	}
+	if q.Kind != "" && q.Kind != KindActivity && q.Kind != KindBead {
+		return nil, fmt.Errorf("invalid kind %q (must be %s or %s)", q.Kind, KindActivity, KindBead)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	return m.store.SearchDocuments(q)
+}
+
+// Terms splits text into lower-case search terms: runs of letters and
+// digits, at most ten. Everything else, including the operators of the
+// backends' query languages, separates terms.
+func Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > maxTerms {
+		words = words[:maxTerms]
+	}
+	return words
+}
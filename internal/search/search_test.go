@@ -0,0 +1,111 @@
+package search
+
+import (
+	"fmt"
+	"testing"
+)
+
+type fakeStore struct {
+	docs    map[string]*Document
+	saves   int
+	queries []Query
+}
+
+func (s *fakeStore) SaveSearchDocument(d *Document) error {
+	s.docs[d.Kind+"/"+d.ID] = d
+	s.saves++
+	return nil
+}
+
+func (s *fakeStore) DeleteSearchDocument(kind, id string) error {
+	delete(s.docs, kind+"/"+id)
+	return nil
+}
+
+func (s *fakeStore) SearchDocuments(q Query) ([]*Result, error) {
+	s.queries = append(s.queries, q)
+	return nil, nil
+}
+
+type fakeBeads map[string]*Document
+
+func (b fakeBeads) BeadDocument(beadID string) (*Document, error) {
+	d, ok := b[beadID]
+	if !ok {
+		return nil, nil
+	}
+	copy := *d
+	return &copy, nil
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Deploy failed", "[deploy failed]"},
+		{`"staging" AND -prod* OR title:x`, "[staging and prod or title x]"},
+		{"  ", "[]"},
+		{"a b c d e f g h i j k l", "[a b c d e f g h i j]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(Terms(tt.text)); got != tt.want {
+			t.Errorf("Terms(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestManager_IndexesChangedBeads(t *testing.T) {
+	store := &fakeStore{docs: make(map[string]*Document)}
+	beads := fakeBeads{"bd-1": {Title: "Fix login", Body: "Users cannot log in"}}
+	m := NewManager(store, beads)
+
+	m.BeadChanged("bd-1")
+	m.IndexPending()
+	doc := store.docs["bead/bd-1"]
+	if doc == nil || doc.Kind != KindBead || doc.ID != "bd-1" || doc.Title != "Fix login" {
+		t.Fatalf("bead not indexed: %+v", store.docs)
+	}
+
+	beads["bd-1"].Body = "Users cannot log in after the upgrade"
+	m.BeadChanged("bd-1")
+	m.IndexPending()
+	if store.saves != 2 || store.docs["bead/bd-1"].Body != beads["bd-1"].Body {
+		t.Errorf("changed bead not reindexed: %d saves", store.saves)
+	}
+
+	delete(beads, "bd-1")
+	m.BeadChanged("bd-1")
+	m.IndexPending()
+	if len(store.docs) != 0 {
+		t.Errorf("removed bead still indexed: %+v", store.docs)
+	}
+}
+
+func TestManager_Search(t *testing.T) {
+	store := &fakeStore{docs: make(map[string]*Document)}
+	m := NewManager(store, fakeBeads{})
+
+	if _, err := m.Search("  ", Query{}); err == nil {
+		t.Error("expected an error for a query without words")
+	}
+	if _, err := m.Search("login", Query{Kind: "agent"}); err == nil {
+		t.Error("expected an error for an invalid kind")
+	}
+	if _, err := m.Search("Login bug", Query{Limit: 1000}); err != nil {
+		t.Fatalf("Search failed: %v", err)
+	}
+	q := store.queries[0]
+	if fmt.Sprint(q.Terms) != "[login bug]" || q.Limit != MaxLimit {
+		t.Errorf("unexpected query: %+v", q)
+	}
+	if _, err := m.Search("login", Query{}); err != nil || store.queries[1].Limit != DefaultLimit {
+		t.Errorf("expected the default limit, got %+v, %v", store.queries[1], err)
+	}
+
+	var nilManager *Manager
+	nilManager.BeadChanged("bd-1")
+	if _, err := nilManager.Search("login", Query{}); err == nil {
+		t.Error("expected an error from a nil manager")
+	}
+}
@@ -0,0 +1,481 @@
+// Package staticanalysis runs gosec, staticcheck and eslint over a project
+// and parses their machine-readable output into findings, so agents get
+// structured diagnostics rather than raw tool output.
+package staticanalysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported tools
+const (
+	ToolGosec       = "gosec"
+	ToolStaticcheck = "staticcheck"
+	ToolESLint      = "eslint"
+)
+
+// Severities of a finding
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// MaxFindings bounds the findings a report keeps, most severe first
+const MaxFindings = 200
+
+// Tools lists the supported tools in the order they run
+var Tools = []string{ToolGosec, ToolStaticcheck, ToolESLint}
+
+var severityRank = map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+var goExts = []string{".go"}
+var jsExts = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx"}
+
+// safeArg matches paths that need no shell quoting
+var safeArg = regexp.MustCompile(`^[A-Za-z0-9_./@+=:,-]+$`)
+
+// Finding is one diagnostic of a tool
+type Finding struct {
+	Tool       string `json:"tool"`
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	File       string `json:"file"` // Relative to the project root
+	Line       int    `json:"line"`
+	Column     int    `json:"column,omitempty"`
+	Message    string `json:"message"`
+	CWE        string `json:"cwe,omitempty"`        // gosec only
+	Confidence string `json:"confidence,omitempty"` // gosec only
+}
+
+// ToolRun is the outcome of running one tool
+type ToolRun struct {
+	Tool     string `json:"tool"`
+	Command  string `json:"command"`
+	ExitCode int    `json:"exit_code"`
+	Findings int    `json:"findings"`
+	Error    string `json:"error,omitempty"` // Set when the tool could not run or its output could not be read
+}
+
+// Report is the outcome of a static analysis run
+type Report struct {
+	RanAt     time.Time      `json:"ran_at"`
+	Tools     []ToolRun      `json:"tools"`
+	Findings  []Finding      `json:"findings"`
+	Counts    map[string]int `json:"counts"`              // Severity -> findings, including those left out
+	Truncated int            `json:"truncated,omitempty"` // Findings left out over MaxFindings
+}
+
+// Executor runs a shell command in the project root and returns its
+// stdout, stderr and exit code. An error means the command could not run.
+type Executor func(ctx context.Context, command string) (stdout, stderr string, exitCode int, err error)
+
+// Detect returns the tools that apply to the project checked out in dir
+func Detect(dir string) []string {
+	var tools []string
+	if exists(filepath.Join(dir, "go.mod")) || hasGlob(dir, "*.go") {
+		tools = append(tools, ToolGosec, ToolStaticcheck)
+	}
+	if exists(filepath.Join(dir, "package.json")) || hasGlob(dir, ".eslintrc*") || hasGlob(dir, "eslint.config.*") {
+		tools = append(tools, ToolESLint)
+	}
+	return tools
+}
+
+// Command returns the command running tool over files, or over the whole
+// project when files is empty, with machine-readable output. dir is the
+// project root, where a project-local eslint is preferred. It returns ""
+// when none of the files are for the tool.
+func Command(tool, dir string, files []string) (string, error) {
+	switch tool {
+	case ToolGosec:
+		targets := goPackages(files)
+		if targets == nil {
+			return "", nil
+		}
+		return "gosec -quiet -fmt=json " + strings.Join(targets, " "), nil
+	case ToolStaticcheck:
+		targets := goPackages(files)
+		if targets == nil {
+			return "", nil
+		}
+		return "staticcheck -f json " + strings.Join(targets, " "), nil
+	case ToolESLint:
+		targets := []string{"."}
+		if len(files) > 0 {
+			targets = filterExts(files, jsExts)
+			if len(targets) == 0 {
+				return "", nil
+			}
+		}
+		bin := "eslint"
+		if exists(filepath.Join(dir, "node_modules", ".bin", "eslint")) {
+			bin = "node_modules/.bin/eslint"
+		}
+		return bin + " -f json " + strings.Join(quoteAll(targets), " "), nil
+	}
+	return "", fmt.Errorf("unsupported static analysis tool %q: use one of %s", tool, strings.Join(Tools, ", "))
+}
+
+// Run runs each tool through exec and gathers their findings, with file
+// paths made relative to the first of roots they are under: the project's
+// work directory and, for sandboxed runs, where it is mounted
+func Run(ctx context.Context, exec Executor, dir string, tools, files []string, roots []string) (*Report, error) {
+	report := &Report{RanAt: time.Now().UTC(), Counts: map[string]int{}}
+	var findings []Finding
+	for _, tool := range tools {
+		command, err := Command(tool, dir, files)
+		if err != nil {
+			return nil, err
+		}
+		if command == "" {
+			continue
+		}
+		run := ToolRun{Tool: tool, Command: command}
+		stdout, stderr, exitCode, err := exec(ctx, command)
+		run.ExitCode = exitCode
+		if err != nil {
+			run.Error = err.Error()
+			report.Tools = append(report.Tools, run)
+			continue
+		}
+		found, err := Parse(tool, []byte(stdout), roots...)
+		switch {
+		case exitCode == 127:
+			run.Error = fmt.Sprintf("%s is not installed", tool)
+		case err != nil && strings.TrimSpace(stdout) == "" && exitCode != 0:
+			run.Error = fmt.Sprintf("exit status %d: %s", exitCode, firstLines(stderr, 5))
+		case err != nil:
+			run.Error = err.Error()
+		}
+		run.Findings = len(found)
+		findings = append(findings, found...)
+		report.Tools = append(report.Tools, run)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] < severityRank[b.Severity]
+		}
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	for _, f := range findings {
+		report.Counts[f.Severity]++
+	}
+	if len(findings) > MaxFindings {
+		report.Truncated = len(findings) - MaxFindings
+		findings = findings[:MaxFindings]
+	}
+	report.Findings = findings
+	if report.Findings == nil {
+		report.Findings = []Finding{}
+	}
+	return report, nil
+}
+
+// Parse reads the JSON output of tool into findings
+func Parse(tool string, output []byte, roots ...string) ([]Finding, error) {
+	var findings []Finding
+	var err error
+	switch tool {
+	case ToolGosec:
+		findings, err = parseGosec(output)
+	case ToolStaticcheck:
+		findings, err = parseStaticcheck(output)
+	case ToolESLint:
+		findings, err = parseESLint(output)
+	default:
+		return nil, fmt.Errorf("unsupported static analysis tool %q", tool)
+	}
+	for i := range findings {
+		findings[i].Tool = tool
+		findings[i].File = relativize(findings[i].File, roots)
+	}
+	return findings, err
+}
+
+func parseGosec(output []byte) ([]Finding, error) {
+	var out struct {
+		Issues []struct {
+			Severity   string `json:"severity"`
+			Confidence string `json:"confidence"`
+			CWE        struct {
+				ID string `json:"id"`
+			} `json:"cwe"`
+			RuleID  string `json:"rule_id"`
+			Details string `json:"details"`
+			File    string `json:"file"`
+			Line    string `json:"line"`
+			Column  string `json:"column"`
+		} `json:"Issues"`
+	}
+	if err := json.Unmarshal(jsonStart(output, '{'), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse gosec output: %w", err)
+	}
+	findings := make([]Finding, 0, len(out.Issues))
+	for _, is := range out.Issues {
+		severity := SeverityInfo
+		switch strings.ToUpper(is.Severity) {
+		case "HIGH":
+			severity = SeverityError
+		case "MEDIUM":
+			severity = SeverityWarning
+		}
+		// Multi-line issues report a range such as "12-14"
+		line, _, _ := strings.Cut(is.Line, "-")
+		f := Finding{
+			Rule:       is.RuleID,
+			Severity:   severity,
+			File:       is.File,
+			Line:       atoi(line),
+			Column:     atoi(is.Column),
+			Message:    is.Details,
+			Confidence: strings.ToLower(is.Confidence),
+		}
+		if is.CWE.ID != "" {
+			f.CWE = "CWE-" + is.CWE.ID
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func parseStaticcheck(output []byte) ([]Finding, error) {
+	var findings []Finding
+	dec := json.NewDecoder(strings.NewReader(string(output)))
+	for dec.More() {
+		var d struct {
+			Code     string `json:"code"`
+			Severity string `json:"severity"`
+			Location struct {
+				File   string `json:"file"`
+				Line   int    `json:"line"`
+				Column int    `json:"column"`
+			} `json:"location"`
+			Message string `json:"message"`
+		}
+		if err := dec.Decode(&d); err != nil {
+			return findings, fmt.Errorf("failed to parse staticcheck output: %w", err)
+		}
+		if d.Severity == "ignored" {
+			continue
+		}
+		severity := SeverityWarning
+		if d.Severity == "error" || d.Code == "compile" {
+			severity = SeverityError
+		}
+		findings = append(findings, Finding{
+			Rule:     d.Code,
+			Severity: severity,
+			File:     d.Location.File,
+			Line:     d.Location.Line,
+			Column:   d.Location.Column,
+			Message:  d.Message,
+		})
+	}
+	return findings, nil
+}
+
+func parseESLint(output []byte) ([]Finding, error) {
+	var files []struct {
+		FilePath string `json:"filePath"`
+		Messages []struct {
+			RuleID   *string `json:"ruleId"`
+			Severity int     `json:"severity"`
+			Fatal    bool    `json:"fatal"`
+			Message  string  `json:"message"`
+			Line     int     `json:"line"`
+			Column   int     `json:"column"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(jsonStart(output, '['), &files); err != nil {
+		return nil, fmt.Errorf("failed to parse eslint output: %w", err)
+	}
+	var findings []Finding
+	for _, file := range files {
+		for _, m := range file.Messages {
+			rule := "parse"
+			if m.RuleID != nil {
+				rule = *m.RuleID
+			}
+			severity := SeverityWarning
+			if m.Severity == 2 || m.Fatal {
+				severity = SeverityError
+			}
+			findings = append(findings, Finding{
+				Rule:     rule,
+				Severity: severity,
+				File:     file.FilePath,
+				Line:     m.Line,
+				Column:   m.Column,
+				Message:  m.Message,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// Summary is a one-line account of the report, e.g.
+// "gosec, staticcheck: 2 errors, 1 warning"
+func (r *Report) Summary() string {
+	var tools []string
+	for _, t := range r.Tools {
+		tools = append(tools, t.Tool)
+	}
+	var counts []string
+	for _, sev := range []string{SeverityError, SeverityWarning, SeverityInfo} {
+		if n := r.Counts[sev]; n > 0 {
+			counts = append(counts, plural(n, sev))
+		}
+	}
+	if len(counts) == 0 {
+		counts = []string{"no findings"}
+	}
+	prefix := strings.Join(tools, ", ")
+	if prefix == "" {
+		prefix = "no tools ran"
+	}
+	return prefix + ": " + strings.Join(counts, ", ")
+}
+
+// Markdown renders the report for a pull request body, listing at most
+// limit findings
+func (r *Report) Markdown(limit int) string {
+	var sb strings.Builder
+	sb.WriteString("## Static analysis\n\n")
+	sb.WriteString(r.Summary())
+	sb.WriteString("\n")
+	for _, t := range r.Tools {
+		if t.Error != "" {
+			fmt.Fprintf(&sb, "\n%s did not complete: %s\n", t.Tool, t.Error)
+		}
+	}
+	if len(r.Findings) == 0 {
+		return sb.String()
+	}
+	sb.WriteString("\n| Severity | Tool | Rule | Location | Message |\n|---|---|---|---|---|\n")
+	for i, f := range r.Findings {
+		if i == limit {
+			fmt.Fprintf(&sb, "\n... and %d more\n", len(r.Findings)-limit+r.Truncated)
+			break
+		}
+		message := strings.ReplaceAll(f.Message, "|", `\|`)
+		fmt.Fprintf(&sb, "| %s | %s | %s | %s:%d | %s |\n", f.Severity, f.Tool, f.Rule, f.File, f.Line, strings.ReplaceAll(message, "\n", " "))
+	}
+	return sb.String()
+}
+
+// goPackages turns files into the package patterns Go tools take: ./...
+// for the whole project, or the directories of the Go files. It returns
+// nil when files has no Go files.
+func goPackages(files []string) []string {
+	if len(files) == 0 {
+		return []string{"./..."}
+	}
+	seen := map[string]bool{}
+	var pkgs []string
+	for _, f := range filterExts(files, goExts) {
+		dir := "./" + path.Dir(filepath.ToSlash(path.Clean(f)))
+		dir = strings.TrimSuffix(dir, "/.")
+		if !seen[dir] {
+			seen[dir] = true
+			pkgs = append(pkgs, dir)
+		}
+	}
+	if len(pkgs) == 0 {
+		return nil
+	}
+	return quoteAll(pkgs)
+}
+
+func filterExts(files []string, exts []string) []string {
+	var out []string
+	for _, f := range files {
+		for _, ext := range exts {
+			if strings.HasSuffix(f, ext) {
+				out = append(out, f)
+				break
+			}
+		}
+	}
+	return out
+}
+
+func quoteAll(args []string) []string {
+	out := make([]string, len(args))
+	for i, a := range args {
+		if safeArg.MatchString(a) {
+			out[i] = a
+		} else {
+			out[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+		}
+	}
+	return out
+}
+
+// relativize makes file relative to the first root it is under
+func relativize(file string, roots []string) string {
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if rel, err := filepath.Rel(root, file); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return filepath.ToSlash(file)
+}
+
+// jsonStart skips anything a tool prints before its JSON document
+func jsonStart(output []byte, open byte) []byte {
+	for i, b := range output {
+		if b == open {
+			return output[i:]
+		}
+	}
+	return output
+}
+
+func firstLines(s string, n int) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	if len(lines) > n {
+		lines = lines[:n]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func plural(n int, word string) string {
+	if n == 1 || word == SeverityInfo {
+		return strconv.Itoa(n) + " " + word
+	}
+	return strconv.Itoa(n) + " " + word + "s"
+}
+
+func atoi(s string) int {
+	n, _ := strconv.Atoi(strings.TrimSpace(s))
+	return n
+}
+
+func exists(p string) bool {
+	_, err := os.Stat(p)
+	return err == nil
+}
+
+func hasGlob(dir, pattern string) bool {
+	matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+	return len(matches) > 0
+}
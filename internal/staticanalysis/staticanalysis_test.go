@@ -0,0 +1,153 @@
+package staticanalysis
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const gosecOutput = `[gosec] 2024/01/01 loading packages
+{
+	"Golang errors": {},
+	"Issues": [
+		{"severity": "MEDIUM", "confidence": "HIGH", "cwe": {"id": "22"}, "rule_id": "G304", "details": "Potential file inclusion via variable", "file": "/workspace/cmd/main.go", "line": "12", "column": "9"},
+		{"severity": "HIGH", "confidence": "MEDIUM", "cwe": {"id": "89"}, "rule_id": "G201", "details": "SQL string formatting", "file": "/workspace/store/db.go", "line": "40-42", "column": "3"}
+	]
+}`
+
+const staticcheckOutput = `{"code":"SA4006","severity":"error","location":{"file":"/src/app/store/db.go","line":7,"column":2},"message":"this value of err is never used"}
+{"code":"ST1003","severity":"warning","location":{"file":"/src/app/main.go","line":3,"column":6},"message":"should not use underscores in Go names"}
+{"code":"U1000","severity":"ignored","location":{"file":"/src/app/main.go","line":9,"column":6},"message":"func x is unused"}
+`
+
+const eslintOutput = `[
+	{"filePath": "/workspace/web/app.js", "messages": [
+		{"ruleId": "no-unused-vars", "severity": 2, "message": "'x' is assigned a value but never used.", "line": 1, "column": 7},
+		{"ruleId": "eqeqeq", "severity": 1, "message": "Expected '===' and instead saw '=='.", "line": 4, "column": 9}
+	]},
+	{"filePath": "/workspace/web/broken.js", "messages": [
+		{"ruleId": null, "fatal": true, "severity": 2, "message": "Parsing error: Unexpected token", "line": 2, "column": 1}
+	]},
+	{"filePath": "/workspace/web/clean.js", "messages": []}
+]`
+
+func TestParse(t *testing.T) {
+	findings, err := Parse(ToolGosec, []byte(gosecOutput), "/workspace")
+	if err != nil {
+		t.Fatalf("gosec: %v", err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("gosec findings = %+v", findings)
+	}
+	if f := findings[1]; f.Severity != SeverityError || f.File != "store/db.go" || f.Line != 40 || f.CWE != "CWE-89" || f.Rule != "G201" || f.Tool != ToolGosec {
+		t.Errorf("gosec finding = %+v", f)
+	}
+
+	findings, err = Parse(ToolStaticcheck, []byte(staticcheckOutput), "/elsewhere", "/src/app")
+	if err != nil {
+		t.Fatalf("staticcheck: %v", err)
+	}
+	if len(findings) != 2 || findings[0].File != "store/db.go" || findings[0].Severity != SeverityError || findings[1].Severity != SeverityWarning {
+		t.Errorf("staticcheck findings = %+v, want the ignored one left out", findings)
+	}
+
+	findings, err = Parse(ToolESLint, []byte(eslintOutput), "/workspace")
+	if err != nil {
+		t.Fatalf("eslint: %v", err)
+	}
+	if len(findings) != 3 || findings[1].Severity != SeverityWarning || findings[2].Rule != "parse" || findings[2].File != "web/broken.js" {
+		t.Errorf("eslint findings = %+v", findings)
+	}
+
+	if _, err := Parse(ToolESLint, []byte("Oops! Something went wrong"), "/workspace"); err == nil {
+		t.Error("expected an error for output that is not JSON")
+	}
+}
+
+func TestCommand(t *testing.T) {
+	dir := t.TempDir()
+	cases := []struct {
+		tool  string
+		files []string
+		want  string
+	}{
+		{ToolGosec, nil, "gosec -quiet -fmt=json ./..."},
+		{ToolStaticcheck, []string{"main.go", "store/db.go", "store/cache.go", "web/app.js"}, "staticcheck -f json . ./store"},
+		{ToolStaticcheck, []string{"web/app.js"}, ""},
+		{ToolESLint, []string{"web/app.js", "main.go", "web/my file.ts"}, "eslint -f json web/app.js 'web/my file.ts'"},
+	}
+	for _, c := range cases {
+		got, err := Command(c.tool, dir, c.files)
+		if err != nil || got != c.want {
+			t.Errorf("Command(%s, %v) = %q, %v; want %q", c.tool, c.files, got, err, c.want)
+		}
+	}
+	if _, err := Command("semgrep", dir, nil); err == nil {
+		t.Error("expected an error for an unsupported tool")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "node_modules", ".bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "node_modules", ".bin", "eslint"), nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Command(ToolESLint, dir, nil); got != "node_modules/.bin/eslint -f json ." {
+		t.Errorf("expected the project's eslint, got %q", got)
+	}
+}
+
+func TestRun(t *testing.T) {
+	outputs := map[string]string{"gosec": gosecOutput, "staticcheck": staticcheckOutput}
+	exec := func(ctx context.Context, command string) (string, string, int, error) {
+		tool := strings.Fields(command)[0]
+		switch tool {
+		case "eslint":
+			return "", "sh: eslint: not found", 127, nil
+		case "staticcheck":
+			return outputs[tool], "", 1, nil
+		case "gosec":
+			return outputs[tool], "", 1, nil
+		}
+		return "", "", 0, errors.New("unexpected command")
+	}
+
+	report, err := Run(context.Background(), exec, t.TempDir(), Tools, nil, []string{"/workspace", "/src/app"})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if len(report.Tools) != 3 || report.Tools[2].Error != "eslint is not installed" || report.Tools[0].Findings != 2 {
+		t.Errorf("tool runs = %+v", report.Tools)
+	}
+	if report.Counts[SeverityError] != 2 || report.Counts[SeverityWarning] != 2 || len(report.Findings) != 4 {
+		t.Errorf("counts = %v, findings = %+v", report.Counts, report.Findings)
+	}
+	if report.Findings[0].Severity != SeverityError || report.Findings[3].Severity != SeverityWarning {
+		t.Errorf("findings not sorted by severity: %+v", report.Findings)
+	}
+	if got := report.Summary(); got != "gosec, staticcheck, eslint: 2 errors, 2 warnings" {
+		t.Errorf("summary = %q", got)
+	}
+	md := report.Markdown(2)
+	if !strings.Contains(md, "eslint did not complete: eslint is not installed") || !strings.Contains(md, "... and 2 more") || !strings.Contains(md, "| error | gosec | G201 | store/db.go:40 |") {
+		t.Errorf("markdown:\n%s", md)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	dir := t.TempDir()
+	if tools := Detect(dir); len(tools) != 0 {
+		t.Errorf("empty project: %v", tools)
+	}
+	for _, name := range []string{"go.mod", "package.json"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tools := Detect(dir); strings.Join(tools, ",") != "gosec,staticcheck,eslint" {
+		t.Errorf("Go and npm project: %v", tools)
+	}
+}
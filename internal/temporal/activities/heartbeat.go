@@ -0,0 +1,51 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// recordHeartbeat reports an activity's progress to Temporal. With a
+// heartbeat timeout set, Temporal fails an attempt whose worker stops
+// heartbeating within that timeout rather than its start-to-close timeout.
+// Heartbeats also carry cancellation back: once the workflow cancels the
+// activity, or the attempt times out, ctx is cancelled. Outside a Temporal
+// activity, as under the embedded scheduler, it does nothing.
+func recordHeartbeat(ctx context.Context, details ...interface{}) {
+	if activity.IsActivity(ctx) {
+		activity.RecordHeartbeat(ctx, details...)
+	}
+}
+
+// keepAlive heartbeats in the background while a single long call, such as
+// an LLM request, runs; call the returned stop once it returns. It beats at
+// a third of the heartbeat timeout, so a dead worker is still noticed and
+// a cancellation still reaches the call through ctx.
+func keepAlive(ctx context.Context) (stop func()) {
+	if !activity.IsActivity(ctx) {
+		return func() {}
+	}
+	every := activity.GetInfo(ctx).HeartbeatTimeout / 3
+	if every <= 0 {
+		every = 10 * time.Second
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				activity.RecordHeartbeat(ctx)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -35,7 +35,8 @@ func NewLoomActivities(db *database.Database, d *dispatch.Dispatcher, b *beads.M
 
 // LoomHeartbeatActivity is the Ralph Loop — the relentless work-draining engine.
 // Each beat: resets stuck agents, resolves stuck beads, then drains all
-// dispatchable work by calling DispatchOnce in a tight loop.
+// dispatchable work by calling DispatchOnce in a tight loop. It heartbeats
+// after each step and stops early, returning ctx's error, once cancelled.
 func (a *LoomActivities) LoomHeartbeatActivity(ctx context.Context, beatCount int) error {
 	start := time.Now()
 	log.Printf("[Ralph] Beat %d: starting (dispatcher=%v agentMgr=%v beadsMgr=%v)", beatCount, a.dispatcher != nil, a.agentMgr != nil, a.beadsMgr != nil)
@@ -46,15 +47,23 @@ func (a *LoomActivities) LoomHeartbeatActivity(ctx context.Context, beatCount in
 		agentsReset = a.agentMgr.ResetStuckAgents(5 * time.Minute)
 	}
 	log.Printf("[Ralph] Beat %d: phase1 done (agentsReset=%d, elapsed=%v)", beatCount, agentsReset, time.Since(start).Round(time.Millisecond))
+	recordHeartbeat(ctx, beatCount, "reset_agents")
 
 	// Phase 2: Auto-block beads stuck in dispatch loops
-	stuckResolved := a.resolveStuckBeads()
+	stuckResolved, err := a.resolveStuckBeads(ctx)
+	if err != nil {
+		return err
+	}
 	log.Printf("[Ralph] Beat %d: phase2 done (stuckResolved=%d, elapsed=%v)", beatCount, stuckResolved, time.Since(start).Round(time.Millisecond))
 
 	// Phase 3: Drain all dispatchable work
 	dispatched := 0
 	if a.dispatcher != nil {
 		for i := 0; i < maxDispatchesPerBeat; i++ {
+			if err := ctx.Err(); err != nil {
+				log.Printf("[Ralph] Beat %d: cancelled after %d dispatches: %v", beatCount, dispatched, err)
+				return err
+			}
 			result, err := a.dispatcher.DispatchOnce(ctx, "")
 			if err != nil {
 				log.Printf("[Ralph] Beat %d: dispatch error on iteration %d: %v", beatCount, i+1, err)
@@ -64,6 +73,7 @@ func (a *LoomActivities) LoomHeartbeatActivity(ctx context.Context, beatCount in
 				break
 			}
 			dispatched++
+			recordHeartbeat(ctx, beatCount, "dispatch", dispatched)
 		}
 	}
 
@@ -75,25 +85,29 @@ func (a *LoomActivities) LoomHeartbeatActivity(ctx context.Context, beatCount in
 }
 
 // resolveStuckBeads finds beads with loop_detected=true that haven't been
-// resolved by Ralph yet, and auto-blocks them.
-func (a *LoomActivities) resolveStuckBeads() int {
+// resolved by Ralph yet, and auto-blocks them. It returns ctx's error if
+// cancelled part way.
+func (a *LoomActivities) resolveStuckBeads(ctx context.Context) (int, error) {
 	if a.beadsMgr == nil {
-		return 0
+		return 0, nil
 	}
 
 	// Only query open/in-progress beads — closed beads can't be stuck.
 	openBeads, err := a.beadsMgr.ListBeads(map[string]interface{}{"status": models.BeadStatusOpen})
 	if err != nil {
-		return 0
+		return 0, nil
 	}
 	inProgressBeads, err := a.beadsMgr.ListBeads(map[string]interface{}{"status": models.BeadStatusInProgress})
 	if err != nil {
-		return 0
+		return 0, nil
 	}
 	candidates := append(openBeads, inProgressBeads...)
 
 	resolved := 0
 	for _, b := range candidates {
+		if err := ctx.Err(); err != nil {
+			return resolved, err
+		}
 		if b == nil || b.Context == nil {
 			continue
 		}
@@ -126,8 +140,9 @@ func (a *LoomActivities) resolveStuckBeads() int {
 		}
 		log.Printf("[Ralph] Auto-blocked stuck bead %s: %s (reassigned to %s)", b.ID, reason, triageAgent)
 		resolved++
+		recordHeartbeat(ctx, "resolve_stuck_beads", resolved)
 	}
-	return resolved
+	return resolved, nil
 }
 
 func (a *LoomActivities) findDefaultTriageAgent(projectID string) string {
@@ -24,11 +24,12 @@ type ProviderHeartbeatInput struct {
 
 // ProviderHeartbeatResult captures heartbeat measurements.
 type ProviderHeartbeatResult struct {
-	ProviderID string    `json:"provider_id"`
-	Status     string    `json:"status"`
-	LatencyMs  int64     `json:"latency_ms"`
-	Error      string    `json:"error,omitempty"`
-	CheckedAt  time.Time `json:"checked_at"`
+	ProviderID string               `json:"provider_id"`
+	Status     string               `json:"status"`
+	LatencyMs  int64                `json:"latency_ms"`
+	Error      string               `json:"error,omitempty"`
+	CheckedAt  time.Time            `json:"checked_at"`
+	ServerLoad *provider.ServerLoad `json:"server_load,omitempty"` // For self-hosted inference servers
 }
 
 // ProviderQueryInput represents a direct provider query.
@@ -108,12 +109,20 @@ func (a *ProviderActivities) ProviderHeartbeatActivity(ctx context.Context, inpu
 		record.ModelScore = score
 	}
 	// Capture context window from model metadata (vLLM provides max_model_len)
+	for _, m := range models {
+		a.catalog.RecordDiscoveredModel(m.ID, m.MaxModelLen)
+	}
 	for _, m := range models {
 		if m.MaxModelLen > 0 && (m.ID == selected || selected == "") {
 			record.ContextWindow = m.MaxModelLen
 			break
 		}
 	}
+	if record.ContextWindow == 0 {
+		if md, ok := a.catalog.ModelMetadata(record.SelectedModel); ok {
+			record.ContextWindow = md.ContextWindow
+		}
+	}
 
 	if discoveredType != "" {
 		record.Type = discoveredType
@@ -126,6 +135,15 @@ func (a *ProviderActivities) ProviderHeartbeatActivity(ctx context.Context, inpu
 	_ = a.database.UpsertProvider(record)
 	a.syncRegistry(record)
 
+	load, err := a.checkServerLoad(ctx, record.ID)
+	if err != nil {
+		result.Status = "failed"
+		result.Error = err.Error()
+		a.persistHeartbeat(result)
+		return result, nil
+	}
+	result.ServerLoad = load
+
 	result.Status = record.Status
 	result.Error = ""
 	a.persistHeartbeat(result)
@@ -161,7 +179,9 @@ func (a *ProviderActivities) ProviderQueryActivity(ctx context.Context, input Pr
 	}
 
 	start := time.Now()
+	stop := keepAlive(ctx)
 	resp, err := regProvider.Protocol.CreateChatCompletion(ctx, req)
+	stop()
 	latencyMs := time.Since(start).Milliseconds()
 	if err != nil {
 		return nil, err
@@ -182,6 +202,32 @@ func (a *ProviderActivities) ProviderQueryActivity(ctx context.Context, input Pr
 	return result, nil
 }
 
+// checkServerLoad checks the health of a self-hosted inference server and
+// records the load it reports, for protocols that report one. A server
+// without metrics is still healthy; its load is just unknown.
+func (a *ProviderActivities) checkServerLoad(ctx context.Context, providerID string) (*provider.ServerLoad, error) {
+	if a.registry == nil {
+		return nil, nil
+	}
+	reg, err := a.registry.Get(providerID)
+	if err != nil {
+		return nil, nil
+	}
+	reporter, ok := reg.Protocol.(provider.LoadReporter)
+	if !ok {
+		return nil, nil
+	}
+	if err := reporter.Health(ctx); err != nil {
+		return nil, fmt.Errorf("health check failed: %w", err)
+	}
+	load, err := reporter.Load(ctx)
+	if err != nil {
+		return nil, nil
+	}
+	a.registry.SetServerLoad(providerID, load)
+	return &load, nil
+}
+
 func (a *ProviderActivities) persistHeartbeat(result *ProviderHeartbeatResult) {
 	if result == nil || a.database == nil {
 		return
@@ -232,6 +278,15 @@ func (a *ProviderActivities) syncRegistry(record *internalmodels.Provider) {
 		}
 	}
 
+	// Fall back to catalog pricing so cost-aware scoring works for
+	// providers without a configured rate
+	costPerMToken := record.CostPerMToken
+	if costPerMToken == 0 {
+		if md, ok := a.catalog.ModelMetadata(selected); ok && md.HasPricing() {
+			costPerMToken = md.BlendedCostPerMToken()
+		}
+	}
+
 	cfg := &provider.ProviderConfig{
 		ID:                     record.ID,
 		Name:                   record.Name,
@@ -248,13 +303,14 @@ func (a *ProviderActivities) syncRegistry(record *internalmodels.Provider) {
 		CapabilityScore:        record.Metrics.OverallScore,
 		ContextWindow:          record.ContextWindow,
 		ModelParamsB:           modelParamsB,
-		CostPerMToken:          record.CostPerMToken,
+		CostPerMToken:          costPerMToken,
+		Tags:                   record.Tags,
 	}
 
 	_ = a.registry.Upsert(cfg)
 
 	// Update dynamic scoring with model parameters and heartbeat latency
-	a.registry.UpdateProviderScore(record.ID, modelParamsB, record.CostPerMToken)
+	a.registry.UpdateProviderScore(record.ID, modelParamsB, costPerMToken)
 	a.registry.UpdateHeartbeatLatency(record.ID, record.LastHeartbeatLatencyMs)
 }
 
@@ -262,18 +318,26 @@ func (a *ProviderActivities) publishProviderUpdate(record *internalmodels.Provid
 	if record == nil || a.eventBus == nil {
 		return
 	}
+	data := map[string]interface{}{
+		"provider_id": record.ID,
+		"status":      record.Status,
+		"latency_ms":  record.LastHeartbeatLatencyMs,
+		"error":       record.LastHeartbeatError,
+		"model":       record.SelectedModel,
+		"configured":  record.ConfiguredModel,
+		"score":       record.ModelScore,
+	}
+	if a.registry != nil {
+		if load, ok := a.registry.ServerLoad(record.ID); ok {
+			data["running"] = load.Running
+			data["waiting"] = load.Waiting
+			data["cache_usage"] = load.CacheUsage
+		}
+	}
 	_ = a.eventBus.Publish(&eventbus.Event{
 		Type:   eventbus.EventTypeProviderUpdated,
 		Source: "provider-heartbeat",
-		Data: map[string]interface{}{
-			"provider_id": record.ID,
-			"status":      record.Status,
-			"latency_ms":  record.LastHeartbeatLatencyMs,
-			"error":       record.LastHeartbeatError,
-			"model":       record.SelectedModel,
-			"configured":  record.ConfiguredModel,
-			"score":       record.ModelScore,
-		},
+		Data:   data,
 	})
 }
 
@@ -375,6 +439,28 @@ func (a *ProviderActivities) discoverAndListModels(ctx context.Context, provider
 		}
 	}
 
+	// Cloud providers speak only their own protocol; probing them as OpenAI
+	// or Ollama endpoints would re-type them
+	if provider.IsCloudType(record.Type) {
+		var apiKey string
+		if record.KeyID != "" && a.keys != nil {
+			apiKey, _ = a.keys.GetKey(record.KeyID)
+		}
+		model := record.ConfiguredModel
+		if model == "" {
+			model = record.Model
+		}
+		protocol, err := provider.NewProtocol(&provider.ProviderConfig{Type: record.Type, Endpoint: record.Endpoint, APIKey: apiKey, Model: model})
+		if err != nil {
+			return record, nil, "", "", err
+		}
+		models, err := protocol.GetModels(ctx)
+		if err != nil {
+			return record, nil, "", "", err
+		}
+		return record, models, record.Type, record.Endpoint, nil
+	}
+
 	var lastErr error
 	for _, c := range candidates {
 		models, probeErr := probeModels(ctx, c)
@@ -427,7 +513,7 @@ func buildProviderCandidates(raw string, preferredOpenAIType string) ([]provider
 	}
 	openAIType := "openai"
 	switch preferredOpenAIType {
-	case "local", "custom", "anthropic", "openai":
+	case "local", "custom", "anthropic", "openai", provider.TypeVLLM, provider.TypeTGI:
 		openAIType = preferredOpenAIType
 	}
 
@@ -479,6 +565,8 @@ func uniqueCandidates(in []providerCandidate) []providerCandidate {
 func probeModels(ctx context.Context, c providerCandidate) ([]provider.Model, error) {
 	client := &http.Client{Timeout: 10 * time.Second}
 	switch c.ProviderType {
+	case provider.TypeVLLM, provider.TypeTGI:
+		return provider.NewSelfHostedProvider(c.ProviderType, c.Endpoint, c.APIKey).GetModels(ctx)
 	case "openai", "local", "custom", "anthropic":
 		url := strings.TrimSuffix(c.Endpoint, "/") + "/models"
 		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
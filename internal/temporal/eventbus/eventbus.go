@@ -24,11 +24,29 @@ const (
 	EventTypeBeadAssigned       EventType = "bead.assigned"
 	EventTypeBeadStatusChange   EventType = "bead.status_change"
 	EventTypeBeadCompleted      EventType = "bead.completed"
+	EventTypeBeadHandedOff      EventType = "bead.handed_off"
+	EventTypeBeadVerifyFailed   EventType = "bead.verification_failed"
+	EventTypeBeadCIStatus       EventType = "bead.ci_status"
+	EventTypeBeadGuidanceAcked  EventType = "bead.guidance_acknowledged"
+	EventTypeBeadPlanProposed   EventType = "bead.plan_proposed"
+	EventTypeBeadPlanApproved   EventType = "bead.plan_approved"
+	EventTypeBeadPlanRejected   EventType = "bead.plan_rejected"
+	EventTypeBeadPatchProposed  EventType = "bead.patch_proposed"
+	EventTypeBeadPatchApproved  EventType = "bead.patch_approved"
+	EventTypeBeadPatchRejected  EventType = "bead.patch_rejected"
+	EventTypeBeadUndone         EventType = "bead.undone"
+	EventTypeBeadFilesLocked    EventType = "bead.files_locked"
+	EventTypeBeadFilesUnlocked  EventType = "bead.files_unlocked"
+	EventTypeBeadFileLockStolen EventType = "bead.file_lock_stolen"
+	EventTypeBeadLockDeadlock   EventType = "bead.file_lock_deadlock"
+	EventTypeBeadModelSwitched  EventType = "bead.model_switched"
 	EventTypeDecisionCreated    EventType = "decision.created"
 	EventTypeDecisionResolved   EventType = "decision.resolved"
 	EventTypeProviderRegistered EventType = "provider.registered"
 	EventTypeProviderDeleted    EventType = "provider.deleted"
 	EventTypeProviderUpdated    EventType = "provider.updated"
+	EventTypeProviderSaturated  EventType = "provider.saturated"
+	EventTypeProviderRecovered  EventType = "provider.recovered"
 	EventTypeProjectCreated     EventType = "project.created"
 	EventTypeProjectUpdated     EventType = "project.updated"
 	EventTypeProjectDeleted     EventType = "project.deleted"
@@ -37,6 +55,12 @@ const (
 	EventTypeWorkflowStarted    EventType = "workflow.started"
 	EventTypeWorkflowCompleted  EventType = "workflow.completed"
 
+	// Golden prompt regression suite events
+	EventTypeGoldenPromptRegression EventType = "golden_prompts.regression"
+
+	// Automated pull request review events
+	EventTypePRReviewed EventType = "pr.reviewed"
+
 	// Motivation system events
 	EventTypeMotivationFired     EventType = "motivation.fired"
 	EventTypeMotivationEnabled   EventType = "motivation.enabled"
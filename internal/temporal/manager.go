@@ -53,14 +53,7 @@ func NewManager(cfg *config.TemporalConfig) (*Manager, error) {
 	w := worker.New(client.GetClient(), cfg.TaskQueue, worker.Options{})
 
 	// Register workflows
-	w.RegisterWorkflow(workflows.AgentLifecycleWorkflow)
-	w.RegisterWorkflow(workflows.BeadProcessingWorkflow)
-	w.RegisterWorkflow(workflows.DecisionWorkflow)
-	w.RegisterWorkflow(workflows.DispatcherWorkflow)
-	w.RegisterWorkflow(eventbus.EventAggregatorWorkflow)
-	w.RegisterWorkflow(workflows.ProviderHeartbeatWorkflow)
-	w.RegisterWorkflow(workflows.ProviderQueryWorkflow)
-	w.RegisterWorkflow(workflows.LoomHeartbeatWorkflow) // Master clock
+	registerWorkflows(w)
 
 	// Register activities
 	if eventBus != nil {
@@ -0,0 +1,158 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/temporalproto"
+	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/sdk/client"
+	sdklog "go.temporal.io/sdk/log"
+	"go.temporal.io/sdk/worker"
+
+	temporalclient "github.com/jordanhubbard/loom/internal/temporal/client"
+	"github.com/jordanhubbard/loom/internal/temporal/eventbus"
+	"github.com/jordanhubbard/loom/internal/temporal/workflows"
+)
+
+// workflowRegistry is the part of a worker or replayer that takes workflows
+type workflowRegistry interface {
+	RegisterWorkflow(w interface{})
+}
+
+// registerWorkflows registers every workflow Loom runs. The worker and the
+// replayer share it, so replay checks exactly the code a worker would run.
+func registerWorkflows(r workflowRegistry) {
+	r.RegisterWorkflow(workflows.AgentLifecycleWorkflow)
+	r.RegisterWorkflow(workflows.BeadProcessingWorkflow)
+	r.RegisterWorkflow(workflows.DecisionWorkflow)
+	r.RegisterWorkflow(workflows.DispatcherWorkflow)
+	r.RegisterWorkflow(eventbus.EventAggregatorWorkflow)
+	r.RegisterWorkflow(workflows.ProviderHeartbeatWorkflow)
+	r.RegisterWorkflow(workflows.ProviderQueryWorkflow)
+	r.RegisterWorkflow(workflows.LoomHeartbeatWorkflow) // Master clock
+}
+
+// ReplayResult is the outcome of replaying one workflow execution
+type ReplayResult struct {
+	WorkflowID   string
+	RunID        string
+	WorkflowType string
+	File         string // Where the history was written, when exported
+	Err          error  // Non-nil if this binary cannot continue the execution
+}
+
+// ReplayHistoryFile replays a workflow history exported as JSON, by `loom
+// workflows export` or `temporal workflow show --output json`, against the
+// workflows in this binary. An error means this code would break the
+// execution the history came from.
+func ReplayHistoryFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	history, err := client.HistoryFromJSON(f, client.HistoryJSONOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read history %s: %w", path, err)
+	}
+	return replayHistory(history)
+}
+
+func replayHistory(history *historypb.History) error {
+	replayer := worker.NewWorkflowReplayer()
+	registerWorkflows(replayer)
+	return replayer.ReplayWorkflowHistory(quietLogger(), history)
+}
+
+// quietLogger drops the replayer's logs; its result is the error it returns
+func quietLogger() sdklog.Logger {
+	return sdklog.NewStructuredLogger(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+// ReplayRunning replays the history of every running execution on the
+// client's task queue against the workflows in this binary. Run it with a
+// new build before deploying it: an execution whose result has an error
+// would fail with a nondeterminism error once the new workers pick it up.
+func ReplayRunning(ctx context.Context, c *temporalclient.Client) ([]ReplayResult, error) {
+	var results []ReplayResult
+	err := forEachRunning(ctx, c, func(result ReplayResult, history *historypb.History) error {
+		result.Err = replayHistory(history)
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// ExportRunning writes the history of every running execution on the
+// client's task queue to dir as <workflow ID>_<run ID>.json, in the format
+// ReplayHistoryFile reads
+func ExportRunning(ctx context.Context, c *temporalclient.Client, dir string) ([]ReplayResult, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	marshal := temporalproto.CustomJSONMarshalOptions{Indent: "  "}
+
+	var results []ReplayResult
+	err := forEachRunning(ctx, c, func(result ReplayResult, history *historypb.History) error {
+		data, err := marshal.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("failed to encode history of %s: %w", result.WorkflowID, err)
+		}
+		name := strings.ReplaceAll(result.WorkflowID+"_"+result.RunID, string(filepath.Separator), "_") + ".json"
+		result.File = filepath.Join(dir, name)
+		if err := os.WriteFile(result.File, data, 0644); err != nil {
+			return err
+		}
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
+
+// forEachRunning calls fn with the full history of each running execution
+// on the client's task queue
+func forEachRunning(ctx context.Context, c *temporalclient.Client, fn func(ReplayResult, *historypb.History) error) error {
+	tc := c.GetClient()
+	request := &workflowservice.ListWorkflowExecutionsRequest{
+		Namespace: c.GetNamespace(),
+		Query:     fmt.Sprintf("ExecutionStatus = 'Running' AND TaskQueue = '%s'", c.GetTaskQueue()),
+	}
+	for {
+		resp, err := tc.ListWorkflow(ctx, request)
+		if err != nil {
+			return fmt.Errorf("failed to list running workflows: %w", err)
+		}
+		for _, info := range resp.GetExecutions() {
+			result := ReplayResult{
+				WorkflowID:   info.GetExecution().GetWorkflowId(),
+				RunID:        info.GetExecution().GetRunId(),
+				WorkflowType: info.GetType().GetName(),
+			}
+			history := &historypb.History{}
+			iter := tc.GetWorkflowHistory(ctx, result.WorkflowID, result.RunID, false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+			for iter.HasNext() {
+				event, err := iter.Next()
+				if err != nil {
+					return fmt.Errorf("failed to fetch history of %s: %w", result.WorkflowID, err)
+				}
+				history.Events = append(history.Events, event)
+			}
+			if err := fn(result, history); err != nil {
+				return err
+			}
+		}
+		if len(resp.GetNextPageToken()) == 0 {
+			return nil
+		}
+		request.NextPageToken = resp.GetNextPageToken()
+	}
+}
@@ -0,0 +1,59 @@
+package temporal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/worker"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/jordanhubbard/loom/internal/temporal/workflows"
+)
+
+// TestReplayRecordedHistories replays a history of each workflow recorded
+// at every version still supported. A failure means a change would break
+// executions that are already running: gate it with workflow.GetVersion.
+func TestReplayRecordedHistories(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "histories", "*.json"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no recorded histories: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			if err := ReplayHistoryFile(file); err != nil {
+				t.Errorf("replay failed: %v", err)
+			}
+		})
+	}
+}
+
+// TestReplayCatchesUngatedChange checks that the harness fails a change
+// made without a version gate: here, beating before the first sleep
+func TestReplayCatchesUngatedChange(t *testing.T) {
+	ungated := func(ctx workflow.Context, input workflows.LoomHeartbeatWorkflowInput) error {
+		ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{StartToCloseTimeout: 10 * time.Minute})
+		for beat := 1; ; beat++ {
+			_ = workflow.ExecuteActivity(ctx, "LoomHeartbeatActivity", beat).Get(ctx, nil)
+			_ = workflow.Sleep(ctx, input.Interval)
+		}
+	}
+
+	f, err := os.Open(filepath.Join("testdata", "histories", "loom_heartbeat_v0.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	history, err := client.HistoryFromJSON(f, client.HistoryJSONOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayer := worker.NewWorkflowReplayer()
+	replayer.RegisterWorkflowWithOptions(ungated, workflow.RegisterOptions{Name: "LoomHeartbeatWorkflow"})
+	if err := replayer.ReplayWorkflowHistory(quietLogger(), history); err == nil {
+		t.Error("replaying a pre-change history with ungated code should fail")
+	}
+}
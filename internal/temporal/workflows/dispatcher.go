@@ -3,7 +3,6 @@ package workflows
 import (
 	"time"
 
-	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
 
@@ -17,13 +16,7 @@ func DispatcherWorkflow(ctx workflow.Context, input DispatcherWorkflowInput) err
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Dispatcher workflow started", "projectID", input.ProjectID, "interval", input.Interval)
 
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: 30 * time.Minute,
-		RetryPolicy: &temporal.RetryPolicy{
-			MaximumAttempts: 3,
-		},
-	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	ctx = withActivityPolicy(ctx, "DispatchOnceActivity")
 
 	if input.Interval <= 0 {
 		input.Interval = 10 * time.Second
@@ -31,11 +24,13 @@ func DispatcherWorkflow(ctx workflow.Context, input DispatcherWorkflowInput) err
 
 	triggerCh := workflow.GetSignalChannel(ctx, "dispatcher.trigger")
 	iteration := 0
+	followSuggestion := workflow.GetVersion(ctx, dispatcherContinueAsNewSuggested, workflow.DefaultVersion, 1) >= 1
 
 	for {
 		_ = workflow.ExecuteActivity(ctx, "DispatchOnceActivity", input.ProjectID).Get(ctx, nil)
 		iteration++
-		if iteration%100 == 0 && workflow.GetInfo(ctx).GetCurrentHistoryLength() > 10000 {
+		info := workflow.GetInfo(ctx)
+		if (iteration%100 == 0 && info.GetCurrentHistoryLength() > 10000) || (followSuggestion && info.GetContinueAsNewSuggested()) {
 			logger.Warn("Dispatcher history too large, continuing as new")
 			return workflow.NewContinueAsNewError(ctx, DispatcherWorkflow, input)
 		}
@@ -0,0 +1,45 @@
+package workflows
+
+import (
+	"sync"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+// Activity options are not recorded in a workflow's history, so changing
+// them affects only activities scheduled afterwards and needs no version gate
+var (
+	policiesMu sync.RWMutex
+	policies   config.TemporalConfig
+)
+
+// SetActivityPolicies replaces the configured activity policies the
+// workflows schedule activities with
+func SetActivityPolicies(activities map[string]config.ActivityPolicy) {
+	policiesMu.Lock()
+	defer policiesMu.Unlock()
+	policies.Activities = activities
+}
+
+// withActivityPolicy sets ctx's activity options to the named activity's
+// policy
+func withActivityPolicy(ctx workflow.Context, name string) workflow.Context {
+	policiesMu.RLock()
+	p := policies.ActivityPolicy(name)
+	policiesMu.RUnlock()
+
+	return workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: p.StartToCloseTimeout,
+		HeartbeatTimeout:    p.HeartbeatTimeout,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:        p.InitialInterval,
+			BackoffCoefficient:     p.BackoffCoefficient,
+			MaximumInterval:        p.MaximumInterval,
+			MaximumAttempts:        int32(p.MaximumAttempts),
+			NonRetryableErrorTypes: p.NonRetryableErrors,
+		},
+	})
+}
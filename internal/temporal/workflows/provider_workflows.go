@@ -3,7 +3,6 @@ package workflows
 import (
 	"time"
 
-	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 
 	"github.com/jordanhubbard/loom/internal/temporal/activities"
@@ -22,13 +21,7 @@ func ProviderHeartbeatWorkflow(ctx workflow.Context, input ProviderHeartbeatWork
 		input.Interval = 30 * time.Second
 	}
 
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: 2 * time.Minute,
-		RetryPolicy: &temporal.RetryPolicy{
-			MaximumAttempts: 1,
-		},
-	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	ctx = withActivityPolicy(ctx, "ProviderHeartbeatActivity")
 
 	for {
 		var result activities.ProviderHeartbeatResult
@@ -53,13 +46,7 @@ type ProviderQueryWorkflowInput struct {
 
 // ProviderQueryWorkflow runs a direct provider query through Temporal.
 func ProviderQueryWorkflow(ctx workflow.Context, input ProviderQueryWorkflowInput) (activities.ProviderQueryResult, error) {
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: 3 * time.Minute,
-		RetryPolicy: &temporal.RetryPolicy{
-			MaximumAttempts: 2,
-		},
-	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	ctx = withActivityPolicy(ctx, "ProviderQueryActivity")
 
 	var result activities.ProviderQueryResult
 	err := workflow.ExecuteActivity(ctx, "ProviderQueryActivity", activities.ProviderQueryInput{
@@ -0,0 +1,17 @@
+package workflows
+
+// Change IDs passed to workflow.GetVersion. An execution started before a
+// change replays its history at workflow.DefaultVersion and keeps the old
+// behaviour until it continues as new; a new run records the newest version
+// in a marker. Never rename or drop a change ID, or the minimum version it
+// supports, while executions that recorded an older version may be running.
+// docs/ADMIN_GUIDE.md ("Upgrading Workflows") describes the upgrade path.
+const (
+	// Version 1: a fresh Ralph Loop beats at once instead of one interval
+	// in, and the beat count carries across continue-as-new
+	loomHeartbeatBeatOnStart = "loom-heartbeat-beat-on-start"
+
+	// Version 1: the dispatcher continues as new whenever the server
+	// suggests it, not only when its history passes 10000 events
+	dispatcherContinueAsNewSuggested = "dispatcher-continue-as-new-suggested"
+)
@@ -269,6 +269,7 @@ func SendEventActivity(ctx workflow.Context, event *eventbus.Event) error {
 // LoomHeartbeatWorkflowInput controls the master heartbeat
 type LoomHeartbeatWorkflowInput struct {
 	Interval time.Duration // How often to beat (default 10s)
+	Beats    int           // Beats completed by earlier runs, carried across continue-as-new
 }
 
 // LoomHeartbeatWorkflow is the Ralph Loop — the relentless work-draining engine.
@@ -282,17 +283,21 @@ func LoomHeartbeatWorkflow(ctx workflow.Context, input LoomHeartbeatWorkflowInpu
 
 	logger.Info("Ralph Loop started", "interval", input.Interval)
 
-	activityOptions := workflow.ActivityOptions{
-		StartToCloseTimeout: 10 * time.Minute, // Ralph drains many beads per beat — LLM calls take time
-		RetryPolicy: &temporal.RetryPolicy{
-			MaximumAttempts: 1, // Don't retry — next beat handles it
-		},
-	}
-	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	// By default a failed beat is not retried: the next beat handles it
+	ctx = withActivityPolicy(ctx, "LoomHeartbeatActivity")
 
+	// Runs that predate the gate sleep before every beat and count from zero
 	beatCount := 0
+	sleep := true
+	if workflow.GetVersion(ctx, loomHeartbeatBeatOnStart, workflow.DefaultVersion, 1) >= 1 {
+		beatCount = input.Beats
+		sleep = input.Beats > 0 // Only a run that continued as new has just beaten
+	}
 	for {
-		_ = workflow.Sleep(ctx, input.Interval)
+		if sleep {
+			_ = workflow.Sleep(ctx, input.Interval)
+		}
+		sleep = true
 		beatCount++
 
 		err := workflow.ExecuteActivity(ctx, "LoomHeartbeatActivity", beatCount).Get(ctx, nil)
@@ -303,6 +308,7 @@ func LoomHeartbeatWorkflow(ctx workflow.Context, input LoomHeartbeatWorkflowInpu
 		// Prevent Temporal history bloat — continue as new every 500 beats (~83 min at 10s)
 		if beatCount%500 == 0 {
 			logger.Info("Ralph Loop continuing as new workflow", "beats_completed", beatCount)
+			input.Beats = beatCount
 			return workflow.NewContinueAsNewError(ctx, LoomHeartbeatWorkflow, input)
 		}
 	}
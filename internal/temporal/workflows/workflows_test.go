@@ -0,0 +1,40 @@
+package workflows
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/testsuite"
+
+	"github.com/jordanhubbard/loom/pkg/config"
+)
+
+func TestLoomHeartbeatUsesActivityPolicy(t *testing.T) {
+	SetActivityPolicies(map[string]config.ActivityPolicy{
+		"LoomHeartbeatActivity": {StartToCloseTimeout: 5 * time.Minute, HeartbeatTimeout: 45 * time.Second},
+	})
+	defer SetActivityPolicies(nil)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	var beats []int
+	var info activity.Info
+	env.RegisterActivityWithOptions(func(ctx context.Context, beat int) error {
+		beats = append(beats, beat)
+		info = activity.GetInfo(ctx)
+		return nil
+	}, activity.RegisterOptions{Name: "LoomHeartbeatActivity"})
+
+	// A new run beats at once, then every interval
+	env.RegisterDelayedCallback(env.CancelWorkflow, 15*time.Second)
+	env.ExecuteWorkflow(LoomHeartbeatWorkflow, LoomHeartbeatWorkflowInput{Interval: 10 * time.Second})
+
+	if len(beats) != 2 || beats[0] != 1 || beats[1] != 2 {
+		t.Errorf("beats = %v, want [1 2]", beats)
+	}
+	if info.StartToCloseTimeout != 5*time.Minute || info.HeartbeatTimeout != 45*time.Second {
+		t.Errorf("activity ran with start-to-close %v, heartbeat %v", info.StartToCloseTimeout, info.HeartbeatTimeout)
+	}
+}
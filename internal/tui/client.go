@@ -0,0 +1,165 @@
+package tui
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Client talks to a Loom server's API on behalf of the terminal UI
+type Client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// NewClient returns a client for the server at baseURL. token is a JWT or
+// API key, sent as a bearer credential; it may be empty when the server
+// does not require authentication.
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+"/api/v1"+path, r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	return req, nil
+}
+
+// do sends a request and decodes the JSON response into out, when given
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkResponse turns an error status into an error carrying the server's
+// message
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if json.Unmarshal(data, &body) == nil && body.Error != "" {
+		return fmt.Errorf("%s: %s", resp.Status, body.Error)
+	}
+	return fmt.Errorf("%s", resp.Status)
+}
+
+// Beads lists beads
+func (c *Client) Beads(ctx context.Context) ([]*models.Bead, error) {
+	var beads []*models.Bead
+	return beads, c.do(ctx, http.MethodGet, "/beads", nil, &beads)
+}
+
+// Agents lists agents
+func (c *Client) Agents(ctx context.Context) ([]*models.Agent, error) {
+	var agents []*models.Agent
+	return agents, c.do(ctx, http.MethodGet, "/agents", nil, &agents)
+}
+
+// Decisions lists decisions
+func (c *Client) Decisions(ctx context.Context) ([]*models.DecisionBead, error) {
+	var decisions []*models.DecisionBead
+	return decisions, c.do(ctx, http.MethodGet, "/decisions", nil, &decisions)
+}
+
+// Decide resolves a decision. The server records the caller as the
+// decider.
+func (c *Client) Decide(ctx context.Context, decisionID, decision, rationale string) error {
+	body := map[string]string{"decision": decision, "rationale": rationale}
+	return c.do(ctx, http.MethodPost, "/decisions/"+url.PathEscape(decisionID)+"/decide", body, nil)
+}
+
+// Stream reads the server-sent event stream at path, calling fn with the
+// name and data of each event, until ctx is done or the stream ends
+func (c *Client) Stream(ctx context.Context, path string, fn func(event string, data []byte)) error {
+	req, err := c.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	// Streams stay open; only ctx ends them
+	resp, err := (&http.Client{Transport: c.http.Transport}).Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	return readEvents(resp.Body, fn)
+}
+
+// readEvents parses a server-sent event stream. Comments, such as
+// keepalives, are skipped.
+func readEvents(r io.Reader, fn func(event string, data []byte)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	event := ""
+	var data [][]byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				if event == "" {
+					event = "message"
+				}
+				fn(event, bytes.Join(data, []byte("\n")))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, []byte(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")))
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,66 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadEvents(t *testing.T) {
+	stream := "event: connected\ndata: {\"message\": \"hi\"}\n\n" +
+		": keepalive\n\n" +
+		"event: bead.created\ndata: {\"id\":\"bd-1\"}\n\n" +
+		"data: line one\ndata: line two\n\n"
+
+	var got []string
+	if err := readEvents(strings.NewReader(stream), func(event string, data []byte) {
+		got = append(got, event+"="+string(data))
+	}); err != nil {
+		t.Fatalf("readEvents failed: %v", err)
+	}
+	want := []string{`connected={"message": "hi"}`, `bead.created={"id":"bd-1"}`, "message=line one\nline two"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestClient_AuthAndDecide(t *testing.T) {
+	var decided map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"Missing authorization header"}`))
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v1/beads":
+			w.Write([]byte(`[{"id":"bd-1","title":"Fix it","status":"open","priority":1}]`))
+		case "/api/v1/decisions/bd-dec-1/decide":
+			json.NewDecoder(r.Body).Decode(&decided)
+			w.Write([]byte(`{"status":"decided"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	if _, err := NewClient(srv.URL, "").Beads(ctx); err == nil || !strings.Contains(err.Error(), "Missing authorization header") {
+		t.Errorf("expected the server's error without a token, got %v", err)
+	}
+
+	c := NewClient(srv.URL+"/", "tok")
+	beads, err := c.Beads(ctx)
+	if err != nil || len(beads) != 1 || beads[0].Title != "Fix it" {
+		t.Fatalf("Beads = %+v, %v", beads, err)
+	}
+	if err := c.Decide(ctx, "bd-dec-1", "approve", "looks right"); err != nil {
+		t.Fatalf("Decide failed: %v", err)
+	}
+	if decided["decision"] != "approve" || decided["rationale"] != "looks right" {
+		t.Errorf("unexpected decide body: %v", decided)
+	}
+}
@@ -0,0 +1,528 @@
+package tui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// maxOutputLines is how much of the followed agent's output is kept
+const maxOutputLines = 500
+
+// reloadDelay coalesces bursts of events into one reload
+const reloadDelay = 300 * time.Millisecond
+
+// reconnectDelayMax caps the backoff between stream reconnects
+const reconnectDelayMax = 30 * time.Second
+
+// pane is a part of the screen that takes the cursor
+type pane int
+
+const (
+	paneBeads pane = iota
+	paneAgents
+	paneDecisions
+	paneCount
+)
+
+// Data kinds, reloaded when events about them arrive
+const (
+	kindBeads     = "beads"
+	kindAgents    = "agents"
+	kindDecisions = "decisions"
+)
+
+type (
+	beadsMsg     []*models.Bead
+	agentsMsg    []*models.Agent
+	decisionsMsg []*models.DecisionBead
+	reloadMsg    string
+	errMsg       struct{ err error }
+	decidedMsg   struct{ id, decision string }
+
+	// eventMsg is an event from the server's event stream
+	eventMsg struct{ event string }
+
+	// streamMsg reports whether the event stream is connected
+	streamMsg struct {
+		live bool
+		err  error
+	}
+
+	// outputMsg is a line of a followed agent's output; reset clears the
+	// output before the stream (re)connects and replays recent lines
+	outputMsg struct {
+		agentID string
+		line    string
+		reset   bool
+	}
+)
+
+// logEntry is a line of agent output from the log stream
+type logEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	Source    string    `json:"source"`
+	Message   string    `json:"message"`
+}
+
+// decisionPrompt collects an operator's answer to a decision: one of its
+// options, or free text when it has none, then a rationale
+type decisionPrompt struct {
+	decision  *models.DecisionBead
+	selected  int
+	answer    []rune
+	rationale []rune
+	step      int // 0 chooses the answer, 1 writes the rationale
+}
+
+func (p *decisionPrompt) choice() string {
+	if len(p.decision.Options) > 0 {
+		return p.decision.Options[p.selected]
+	}
+	return strings.TrimSpace(string(p.answer))
+}
+
+// Model is the terminal UI's state
+type Model struct {
+	client *Client
+	server string
+	ctx    context.Context
+	msgs   chan tea.Msg // Messages from the streams
+
+	width, height int
+	focus         pane
+	cursor        [paneCount]int
+
+	beads     []*models.Bead // The queue: beads not closed, most urgent first
+	agents    []*models.Agent
+	decisions []*models.DecisionBead // Pending decisions, most urgent first
+	reloading map[string]bool
+
+	following   string // Agent whose output is shown
+	stopOutput  context.CancelFunc
+	output      []string
+	live        bool
+	streamErr   string
+	status      string
+	prompt      *decisionPrompt
+	lastRefresh time.Time
+	quitting    bool
+}
+
+// NewModel returns the UI for the server client talks to. Streams stop
+// when ctx is done.
+func NewModel(ctx context.Context, client *Client, server string) *Model {
+	return &Model{
+		client:    client,
+		server:    server,
+		ctx:       ctx,
+		msgs:      make(chan tea.Msg, 256),
+		reloading: make(map[string]bool),
+	}
+}
+
+// Init loads everything and starts following the event stream
+func (m *Model) Init() tea.Cmd {
+	go m.streamEvents()
+	return tea.Batch(m.load(kindBeads), m.load(kindAgents), m.load(kindDecisions), m.waitForStream())
+}
+
+// send passes a message from a stream to the UI unless it has stopped
+func (m *Model) send(ctx context.Context, msg tea.Msg) bool {
+	select {
+	case m.msgs <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (m *Model) waitForStream() tea.Cmd {
+	return func() tea.Msg {
+		select {
+		case msg := <-m.msgs:
+			return msg
+		case <-m.ctx.Done():
+			return nil
+		}
+	}
+}
+
+// follow reads the stream at path until ctx is done, reconnecting with
+// backoff. connect is called before each attempt.
+func (m *Model) follow(ctx context.Context, path string, connect func() bool, fn func(event string, data []byte), dropped func(error) bool) {
+	delay := time.Second
+	for {
+		if !connect() {
+			return
+		}
+		start := time.Now()
+		err := m.client.Stream(ctx, path, fn)
+		if ctx.Err() != nil || !dropped(err) {
+			return
+		}
+		if time.Since(start) > reconnectDelayMax {
+			delay = time.Second
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+		delay = min(delay*2, reconnectDelayMax)
+	}
+}
+
+// streamEvents turns the server's events into reloads of what they change
+func (m *Model) streamEvents() {
+	ctx := m.ctx
+	m.follow(ctx, "/events/stream",
+		func() bool { return true },
+		func(event string, _ []byte) {
+			if event == "connected" {
+				m.send(ctx, streamMsg{live: true})
+				return
+			}
+			m.send(ctx, eventMsg{event: event})
+		},
+		func(err error) bool { return m.send(ctx, streamMsg{err: err}) })
+}
+
+// streamOutput follows an agent's log lines
+func (m *Model) streamOutput(ctx context.Context, agentID string) {
+	m.follow(ctx, "/logs/stream?agent_id="+url.QueryEscape(agentID),
+		func() bool { return m.send(ctx, outputMsg{agentID: agentID, reset: true}) },
+		func(event string, data []byte) {
+			if event != "log" {
+				return
+			}
+			var entry logEntry
+			if json.Unmarshal(data, &entry) != nil {
+				return
+			}
+			line := fmt.Sprintf("%s %-5s %s", entry.Timestamp.Local().Format("15:04:05"), strings.ToUpper(entry.Level), entry.Message)
+			for _, l := range strings.Split(strings.TrimRight(line, "\n"), "\n") {
+				m.send(ctx, outputMsg{agentID: agentID, line: l})
+			}
+		},
+		func(err error) bool {
+			if err != nil {
+				return m.send(ctx, outputMsg{agentID: agentID, line: "-- output stream dropped: " + err.Error()})
+			}
+			return true
+		})
+}
+
+// load fetches one kind of data
+func (m *Model) load(kind string) tea.Cmd {
+	client, ctx := m.client, m.ctx
+	return func() tea.Msg {
+		var msg tea.Msg
+		var err error
+		switch kind {
+		case kindBeads:
+			var beads []*models.Bead
+			beads, err = client.Beads(ctx)
+			msg = beadsMsg(beads)
+		case kindAgents:
+			var agents []*models.Agent
+			agents, err = client.Agents(ctx)
+			msg = agentsMsg(agents)
+		case kindDecisions:
+			var decisions []*models.DecisionBead
+			decisions, err = client.Decisions(ctx)
+			msg = decisionsMsg(decisions)
+		}
+		if err != nil {
+			return errMsg{err}
+		}
+		return msg
+	}
+}
+
+// scheduleReload reloads a kind of data shortly, once however many events
+// about it arrive meanwhile
+func (m *Model) scheduleReload(kind string) tea.Cmd {
+	if m.reloading[kind] {
+		return nil
+	}
+	m.reloading[kind] = true
+	return tea.Tick(reloadDelay, func(time.Time) tea.Msg { return reloadMsg(kind) })
+}
+
+// eventKind is the kind of data an event changes, or "" for none shown
+func eventKind(event string) string {
+	switch {
+	case strings.HasPrefix(event, "decision."):
+		return kindDecisions
+	case strings.HasPrefix(event, "bead."):
+		return kindBeads
+	case strings.HasPrefix(event, "agent.") && event != "agent.heartbeat":
+		return kindAgents
+	}
+	return ""
+}
+
+// queue returns the beads waiting or being worked, most urgent first
+func queue(beads []*models.Bead) []*models.Bead {
+	var q []*models.Bead
+	for _, b := range beads {
+		if b != nil && b.Status != models.BeadStatusClosed && b.Type != "decision" {
+			q = append(q, b)
+		}
+	}
+	sort.SliceStable(q, func(i, j int) bool {
+		if q[i].Priority != q[j].Priority {
+			return q[i].Priority < q[j].Priority
+		}
+		return q[i].CreatedAt.Before(q[j].CreatedAt)
+	})
+	return q
+}
+
+// pendingDecisions returns the decisions still open, most urgent first
+func pendingDecisions(decisions []*models.DecisionBead) []*models.DecisionBead {
+	var pending []*models.DecisionBead
+	for _, d := range decisions {
+		if d != nil && d.Bead != nil && d.Decision == "" && d.Status != models.BeadStatusClosed {
+			pending = append(pending, d)
+		}
+	}
+	sort.SliceStable(pending, func(i, j int) bool {
+		if pending[i].Priority != pending[j].Priority {
+			return pending[i].Priority < pending[j].Priority
+		}
+		return pending[i].CreatedAt.Before(pending[j].CreatedAt)
+	})
+	return pending
+}
+
+func (m *Model) paneLen(p pane) int {
+	switch p {
+	case paneBeads:
+		return len(m.beads)
+	case paneAgents:
+		return len(m.agents)
+	case paneDecisions:
+		return len(m.decisions)
+	}
+	return 0
+}
+
+// clampCursors keeps every cursor on an item after a list changes
+func (m *Model) clampCursors() {
+	for p := pane(0); p < paneCount; p++ {
+		m.cursor[p] = max(0, min(m.cursor[p], m.paneLen(p)-1))
+	}
+}
+
+// followAgent switches the output pane to an agent
+func (m *Model) followAgent(agentID string) {
+	if agentID == "" || agentID == m.following {
+		return
+	}
+	if m.stopOutput != nil {
+		m.stopOutput()
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	m.following, m.stopOutput, m.output = agentID, cancel, nil
+	go m.streamOutput(ctx, agentID)
+}
+
+// Update handles a message
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		return m, nil
+
+	case tea.KeyMsg:
+		if m.prompt != nil {
+			return m, m.updatePrompt(msg)
+		}
+		return m, m.updateKey(msg)
+
+	case beadsMsg:
+		m.beads = queue(msg)
+		m.lastRefresh = time.Now()
+		m.clampCursors()
+		return m, nil
+
+	case agentsMsg:
+		m.agents = msg
+		sort.SliceStable(m.agents, func(i, j int) bool { return m.agents[i].Name < m.agents[j].Name })
+		m.clampCursors()
+		return m, nil
+
+	case decisionsMsg:
+		m.decisions = pendingDecisions(msg)
+		m.clampCursors()
+		return m, nil
+
+	case reloadMsg:
+		m.reloading[string(msg)] = false
+		return m, m.load(string(msg))
+
+	case errMsg:
+		m.status = "Error: " + msg.err.Error()
+		return m, nil
+
+	case decidedMsg:
+		m.status = fmt.Sprintf("Decided %s: %s", msg.id, msg.decision)
+		return m, m.load(kindDecisions)
+
+	case eventMsg:
+		var cmd tea.Cmd
+		if kind := eventKind(msg.event); kind != "" {
+			cmd = m.scheduleReload(kind)
+		}
+		return m, tea.Batch(cmd, m.waitForStream())
+
+	case streamMsg:
+		m.live = msg.live
+		if msg.err != nil {
+			m.streamErr = msg.err.Error()
+		} else if msg.live {
+			m.streamErr = ""
+		}
+		// Catch up on whatever changed while disconnected
+		var cmds []tea.Cmd
+		if msg.live {
+			cmds = append(cmds, m.load(kindBeads), m.load(kindAgents), m.load(kindDecisions))
+		}
+		return m, tea.Batch(append(cmds, m.waitForStream())...)
+
+	case outputMsg:
+		if msg.agentID == m.following {
+			if msg.reset {
+				m.output = nil
+			} else {
+				m.output = append(m.output, msg.line)
+				if len(m.output) > maxOutputLines {
+					m.output = m.output[len(m.output)-maxOutputLines:]
+				}
+			}
+		}
+		return m, m.waitForStream()
+	}
+	return m, nil
+}
+
+func (m *Model) updateKey(msg tea.KeyMsg) tea.Cmd {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		m.quitting = true
+		if m.stopOutput != nil {
+			m.stopOutput()
+		}
+		return tea.Quit
+	case "tab":
+		m.focus = (m.focus + 1) % paneCount
+	case "shift+tab":
+		m.focus = (m.focus + paneCount - 1) % paneCount
+	case "up", "k":
+		m.cursor[m.focus] = max(0, m.cursor[m.focus]-1)
+	case "down", "j":
+		m.cursor[m.focus] = max(0, min(m.cursor[m.focus]+1, m.paneLen(m.focus)-1))
+	case "r":
+		m.status = "Refreshing..."
+		return tea.Batch(m.load(kindBeads), m.load(kindAgents), m.load(kindDecisions))
+	case "enter":
+		switch m.focus {
+		case paneBeads:
+			if len(m.beads) > 0 {
+				if agent := m.beads[m.cursor[paneBeads]].AssignedTo; agent != "" {
+					m.followAgent(agent)
+				} else {
+					m.status = "Bead is not assigned to an agent"
+				}
+			}
+		case paneAgents:
+			if len(m.agents) > 0 {
+				m.followAgent(m.agents[m.cursor[paneAgents]].ID)
+			}
+		case paneDecisions:
+			if len(m.decisions) > 0 {
+				m.prompt = &decisionPrompt{decision: m.decisions[m.cursor[paneDecisions]]}
+				m.status = ""
+			}
+		}
+	}
+	return nil
+}
+
+func (m *Model) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	p := m.prompt
+	switch msg.Type {
+	case tea.KeyCtrlC:
+		m.prompt = nil
+		return m.updateKey(msg)
+	case tea.KeyEsc:
+		m.prompt = nil
+		m.status = "Decision cancelled"
+		return nil
+	case tea.KeyEnter:
+		if p.step == 0 {
+			if p.choice() == "" {
+				return nil
+			}
+			p.step = 1
+			return nil
+		}
+		rationale := strings.TrimSpace(string(p.rationale))
+		if rationale == "" {
+			return nil
+		}
+		m.prompt = nil
+		client, ctx := m.client, m.ctx
+		id, decision := p.decision.ID, p.choice()
+		return func() tea.Msg {
+			if err := client.Decide(ctx, id, decision, rationale); err != nil {
+				return errMsg{err}
+			}
+			return decidedMsg{id: id, decision: decision}
+		}
+	case tea.KeyUp, tea.KeyLeft:
+		if p.step == 0 && p.selected > 0 {
+			p.selected--
+		}
+		return nil
+	case tea.KeyDown, tea.KeyRight:
+		if p.step == 0 && p.selected < len(p.decision.Options)-1 {
+			p.selected++
+		}
+		return nil
+	case tea.KeyBackspace:
+		text := &p.rationale
+		if p.step == 0 {
+			text = &p.answer
+		}
+		if len(*text) > 0 {
+			*text = (*text)[:len(*text)-1]
+		}
+		return nil
+	case tea.KeySpace, tea.KeyRunes:
+		if p.step == 0 && len(p.decision.Options) > 0 {
+			// Number keys pick an option
+			if len(msg.Runes) == 1 && msg.Runes[0] >= '1' && int(msg.Runes[0]-'1') < len(p.decision.Options) {
+				p.selected = int(msg.Runes[0] - '1')
+			}
+			return nil
+		}
+		text := &p.rationale
+		if p.step == 0 {
+			text = &p.answer
+		}
+		*text = append(*text, msg.Runes...)
+		return nil
+	}
+	return nil
+}
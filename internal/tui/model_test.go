@@ -0,0 +1,98 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestQueue_OrdersOpenBeadsByUrgency(t *testing.T) {
+	now := time.Now()
+	bead := func(id string, status models.BeadStatus, p models.BeadPriority, age time.Duration) *models.Bead {
+		b := &models.Bead{ID: id, Type: "task", Status: status, Priority: p}
+		b.CreatedAt = now.Add(-age)
+		return b
+	}
+	decision := bead("bd-dec", models.BeadStatusOpen, models.BeadPriorityP0, 0)
+	decision.Type = "decision"
+
+	q := queue([]*models.Bead{
+		bead("p2-old", models.BeadStatusOpen, models.BeadPriorityP2, time.Hour),
+		bead("closed", models.BeadStatusClosed, models.BeadPriorityP0, time.Hour),
+		bead("p0", models.BeadStatusInProgress, models.BeadPriorityP0, time.Minute),
+		bead("p2-new", models.BeadStatusBlocked, models.BeadPriorityP2, time.Minute),
+		decision,
+	})
+	var ids []string
+	for _, b := range q {
+		ids = append(ids, b.ID)
+	}
+	if len(ids) != 3 || ids[0] != "p0" || ids[1] != "p2-old" || ids[2] != "p2-new" {
+		t.Errorf("queue = %v, want [p0 p2-old p2-new]", ids)
+	}
+}
+
+func TestModel_EventsCoalesceIntoOneReload(t *testing.T) {
+	m := NewModel(context.Background(), NewClient("http://localhost:0", ""), "test")
+	if eventKind("agent.heartbeat") != "" || eventKind("bead.assigned") != kindBeads {
+		t.Fatal("unexpected event kinds")
+	}
+	if cmd := m.scheduleReload(kindBeads); cmd == nil {
+		t.Fatal("expected the first event to schedule a reload")
+	}
+	if cmd := m.scheduleReload(kindBeads); cmd != nil {
+		t.Error("expected a pending reload to absorb later events")
+	}
+	m.Update(reloadMsg(kindBeads))
+	if m.reloading[kindBeads] {
+		t.Error("expected the reload to clear the pending flag")
+	}
+}
+
+func TestModel_DecisionPrompt(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m := NewModel(ctx, NewClient("http://localhost:0", ""), "test")
+	d := &models.DecisionBead{Bead: &models.Bead{ID: "bd-dec-1", Status: models.BeadStatusOpen}, Question: "Ship it?", Options: []string{"yes", "no"}}
+	m.Update(decisionsMsg{d, {Bead: &models.Bead{ID: "bd-dec-2"}, Decision: "done"}})
+	if len(m.decisions) != 1 {
+		t.Fatalf("expected decided decisions to be dropped, got %d", len(m.decisions))
+	}
+
+	key := func(s string) tea.KeyMsg {
+		switch s {
+		case "enter":
+			return tea.KeyMsg{Type: tea.KeyEnter}
+		case "tab":
+			return tea.KeyMsg{Type: tea.KeyTab}
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+	m.Update(key("tab"))
+	m.Update(key("tab"))
+	m.Update(key("enter"))
+	if m.prompt == nil {
+		t.Fatal("expected enter on a decision to open the prompt")
+	}
+	m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+	if view := m.View(); !strings.Contains(view, "Ship it?") || !strings.Contains(view, "1 yes") {
+		t.Errorf("expected the prompt to show the question and options, got:\n%s", view)
+	}
+	m.Update(key("2"))
+	m.Update(key("enter"))
+	if m.prompt.choice() != "no" || m.prompt.step != 1 {
+		t.Fatalf("expected option 2 to be chosen, got %q at step %d", m.prompt.choice(), m.prompt.step)
+	}
+	// The rationale is required
+	if m.Update(key("enter")); m.prompt == nil {
+		t.Fatal("expected an empty rationale not to submit")
+	}
+	m.Update(key("not yet"))
+	if _, cmd := m.Update(key("enter")); cmd == nil || m.prompt != nil {
+		t.Error("expected the decision to be submitted")
+	}
+}
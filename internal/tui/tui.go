@@ -0,0 +1,24 @@
+// Package tui is a terminal client for operators: it shows the bead queue,
+// agent status, a followed agent's output and pending decisions, kept
+// current by the server's event stream, and answers decisions.
+package tui
+
+import (
+	"context"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Run shows the terminal UI for the server at baseURL until the operator
+// quits or ctx is done
+func Run(ctx context.Context, baseURL, token string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	model := NewModel(ctx, NewClient(baseURL, token), baseURL)
+	_, err := tea.NewProgram(model, tea.WithAltScreen(), tea.WithContext(ctx)).Run()
+	if err == tea.ErrProgramKilled && ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,263 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+var (
+	titleStyle    = lipgloss.NewStyle().Bold(true)
+	mutedStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("244"))
+	selectedStyle = lipgloss.NewStyle().Reverse(true)
+	liveStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	downStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	urgentStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Bold(true)
+	highStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	paneStyle     = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("240"))
+	focusStyle    = paneStyle.BorderForeground(lipgloss.Color("6"))
+)
+
+// agentStatusStyles colour agents by what they are doing
+var agentStatusStyles = map[string]lipgloss.Style{
+	"working":  liveStyle,
+	"deciding": highStyle,
+	"blocked":  urgentStyle,
+	"paused":   mutedStyle,
+}
+
+// View renders the screen
+func (m *Model) View() string {
+	if m.quitting {
+		return ""
+	}
+	if m.width == 0 || m.height == 0 {
+		return "Connecting to " + m.server + "..."
+	}
+
+	header := m.header()
+	footer := m.footer()
+	bodyHeight := m.height - lipgloss.Height(header) - lipgloss.Height(footer)
+	leftWidth := m.width / 2
+	rightWidth := m.width - leftWidth
+	topHeight := bodyHeight / 2
+	bottomHeight := bodyHeight - topHeight
+
+	left := lipgloss.JoinVertical(lipgloss.Left,
+		m.pane(paneBeads, fmt.Sprintf("Bead queue (%d)", len(m.beads)), m.beadLines(), leftWidth, topHeight),
+		m.pane(paneDecisions, fmt.Sprintf("Decisions (%d pending)", len(m.decisions)), m.decisionLines(), leftWidth, bottomHeight))
+	right := lipgloss.JoinVertical(lipgloss.Left,
+		m.pane(paneAgents, fmt.Sprintf("Agents (%d)", len(m.agents)), m.agentLines(), rightWidth, topHeight),
+		m.outputPane(rightWidth, bottomHeight))
+	return lipgloss.JoinVertical(lipgloss.Left, header, lipgloss.JoinHorizontal(lipgloss.Top, left, right), footer)
+}
+
+func (m *Model) header() string {
+	stream := liveStyle.Render("● live")
+	if !m.live {
+		stream = downStyle.Render("● reconnecting")
+		if m.streamErr != "" {
+			stream += mutedStyle.Render(" (" + m.streamErr + ")")
+		}
+	}
+	refreshed := ""
+	if !m.lastRefresh.IsZero() {
+		refreshed = mutedStyle.Render(" · updated " + m.lastRefresh.Format("15:04:05"))
+	}
+	return truncate(titleStyle.Render("Loom")+" "+mutedStyle.Render(m.server)+"  "+stream+refreshed, m.width)
+}
+
+func (m *Model) footer() string {
+	if m.prompt != nil {
+		return m.promptView()
+	}
+	help := mutedStyle.Render("tab switch pane · ↑/↓ move · enter follow agent / answer decision · r refresh · q quit")
+	if m.status != "" {
+		return truncate(m.status, m.width) + "\n" + truncate(help, m.width)
+	}
+	return truncate(help, m.width)
+}
+
+// pane renders a bordered list, scrolled to keep the cursor in view
+func (m *Model) pane(p pane, title string, lines []string, width, height int) string {
+	style := paneStyle
+	if m.focus == p {
+		style = focusStyle
+	}
+	inner := max(0, width-2)
+	rows := max(0, height-3) // Borders and the title
+	content := []string{titleStyle.Render(truncate(title, inner))}
+	if len(lines) == 0 {
+		content = append(content, mutedStyle.Render("(none)"))
+	}
+	offset := 0
+	if cursor := m.cursor[p]; cursor >= rows {
+		offset = cursor - rows + 1
+	}
+	for i := offset; i < len(lines) && i < offset+rows; i++ {
+		line := truncate(lines[i], inner)
+		if m.focus == p && i == m.cursor[p] {
+			line = selectedStyle.Render(pad(line, inner))
+		}
+		content = append(content, line)
+	}
+	return style.Width(inner).Height(max(0, height-2)).Render(strings.Join(content, "\n"))
+}
+
+// outputPane shows the tail of the followed agent's output
+func (m *Model) outputPane(width, height int) string {
+	inner := max(0, width-2)
+	rows := max(0, height-3)
+	title := "Output"
+	if m.following != "" {
+		title = "Output: " + m.following
+	}
+	content := []string{titleStyle.Render(truncate(title, inner))}
+	switch {
+	case m.following == "":
+		content = append(content, mutedStyle.Render("Select an agent, or an assigned bead, and press enter"))
+	case len(m.output) == 0:
+		content = append(content, mutedStyle.Render("Waiting for output..."))
+	}
+	start := max(0, len(m.output)-rows)
+	for _, line := range m.output[start:] {
+		content = append(content, truncate(line, inner))
+	}
+	return paneStyle.Width(inner).Height(max(0, height-2)).Render(strings.Join(content, "\n"))
+}
+
+func (m *Model) beadLines() []string {
+	lines := make([]string, 0, len(m.beads))
+	for _, b := range m.beads {
+		assignee := ""
+		if b.AssignedTo != "" {
+			assignee = mutedStyle.Render(" → " + b.AssignedTo)
+		}
+		lines = append(lines, fmt.Sprintf("%s %-11s %s %s%s", priority(b.Priority), b.Status, mutedStyle.Render(b.ID), b.Title, assignee))
+	}
+	return lines
+}
+
+func (m *Model) agentLines() []string {
+	lines := make([]string, 0, len(m.agents))
+	for _, a := range m.agents {
+		status := a.Status
+		if style, ok := agentStatusStyles[a.Status]; ok {
+			status = style.Render(fmt.Sprintf("%-8s", a.Status))
+		} else {
+			status = fmt.Sprintf("%-8s", a.Status)
+		}
+		bead := ""
+		if a.CurrentBead != "" {
+			bead = " on " + a.CurrentBead
+		}
+		name := a.Name
+		if name == "" {
+			name = a.ID
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s%s %s", status, name, mutedStyle.Render(a.PersonaName), bead, mutedStyle.Render(ago(a.LastActive))))
+	}
+	return lines
+}
+
+func (m *Model) decisionLines() []string {
+	lines := make([]string, 0, len(m.decisions))
+	for _, d := range m.decisions {
+		question := d.Question
+		if question == "" {
+			question = d.Title
+		}
+		question = strings.Join(strings.Fields(question), " ")
+		lines = append(lines, fmt.Sprintf("%s %s %s", priority(d.Priority), mutedStyle.Render(d.ID), question))
+	}
+	return lines
+}
+
+// promptView asks for a decision's answer, then its rationale
+func (m *Model) promptView() string {
+	p := m.prompt
+	d := p.decision
+	question := d.Question
+	if question == "" {
+		question = d.Title
+	}
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Decision "+d.ID) + "\n")
+	for _, line := range strings.Split(strings.TrimSpace(question), "\n") {
+		b.WriteString(truncate(line, m.width) + "\n")
+	}
+	if d.Recommendation != "" {
+		b.WriteString(mutedStyle.Render("Recommended: "+d.Recommendation) + "\n")
+	}
+	if p.step == 0 {
+		if len(d.Options) > 0 {
+			options := make([]string, len(d.Options))
+			for i, o := range d.Options {
+				options[i] = fmt.Sprintf("%d %s", i+1, o)
+				if i == p.selected {
+					options[i] = selectedStyle.Render(options[i])
+				}
+			}
+			b.WriteString(strings.Join(options, "  ") + "\n")
+			b.WriteString(mutedStyle.Render("←/→ or number to choose · enter next · esc cancel"))
+		} else {
+			b.WriteString("Decision: " + string(p.answer) + "█\n")
+			b.WriteString(mutedStyle.Render("enter next · esc cancel"))
+		}
+		return b.String()
+	}
+	b.WriteString("Decision: " + p.choice() + "\n")
+	b.WriteString("Rationale: " + string(p.rationale) + "█\n")
+	b.WriteString(mutedStyle.Render("enter submit · esc cancel"))
+	return b.String()
+}
+
+func priority(p models.BeadPriority) string {
+	label := fmt.Sprintf("P%d", p)
+	switch p {
+	case models.BeadPriorityP0:
+		return urgentStyle.Render(label)
+	case models.BeadPriorityP1:
+		return highStyle.Render(label)
+	}
+	return label
+}
+
+// ago describes how long ago t was, briefly
+func ago(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	d := time.Since(t).Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds ago", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	}
+	return t.Local().Format("Jan 2 15:04")
+}
+
+// truncate cuts s, which may contain styling, to width cells
+func truncate(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	return lipgloss.NewStyle().MaxWidth(width).Render(s)
+}
+
+// pad fills s with spaces to width cells
+func pad(s string, width int) string {
+	if w := lipgloss.Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
@@ -0,0 +1,288 @@
+// Package verification runs a project's test suite before a bead may be
+// closed. A verification passes when the tests pass and, with a coverage
+// threshold set, the coverage they report reaches it; anything else keeps
+// the bead open. Projects may override the global settings, e.g. to raise
+// the threshold or to name their own test command.
+package verification
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Verification statuses
+const (
+	StatusPassed        = "passed"
+	StatusFailed        = "failed"         // The tests failed
+	StatusCoverageBelow = "coverage_below" // The tests passed with too little coverage
+	StatusError         = "error"          // The tests could not be run or measured
+)
+
+// maxOutputChars bounds the test output kept with a result; the tail is
+// kept, since that is where failures are summarized
+const maxOutputChars = 8000
+
+// maxFailedTests bounds the failed tests listed in a result
+const maxFailedTests = 50
+
+// Config tunes verification for a project
+type Config struct {
+	Enabled           bool          // Verify beads before they close
+	Command           string        // Test command; empty picks one for the project's framework
+	CoverageThreshold float64       // Minimum coverage in percent; 0 does not gate on coverage
+	BeadTypes         []string      // Bead types verified; empty verifies every type
+	Timeout           time.Duration // Per run
+}
+
+// DefaultConfig gives a test run ten minutes
+func DefaultConfig() Config {
+	return Config{Timeout: 10 * time.Minute}
+}
+
+// Result is one run of a project's tests for a bead
+type Result struct {
+	BeadID            string    `json:"bead_id"`
+	ProjectID         string    `json:"project_id"`
+	Command           string    `json:"command"`
+	Status            string    `json:"status"`
+	Passed            bool      `json:"passed"`
+	ExitCode          int       `json:"exit_code"`
+	Coverage          *float64  `json:"coverage,omitempty"` // Percent, when the output reports it
+	CoverageThreshold float64   `json:"coverage_threshold,omitempty"`
+	FailedTests       []string  `json:"failed_tests,omitempty"`
+	Output            string    `json:"output,omitempty"` // Tail of the combined output
+	Error             string    `json:"error,omitempty"`
+	FollowUpBeadID    string    `json:"follow_up_bead_id,omitempty"` // Bead filed to fix the failure
+	StartedAt         time.Time `json:"started_at"`
+	FinishedAt        time.Time `json:"finished_at"`
+}
+
+// Summary describes a result in one line
+func (r *Result) Summary() string {
+	switch r.Status {
+	case StatusPassed:
+		if r.Coverage != nil {
+			return fmt.Sprintf("tests passed with %.1f%% coverage", *r.Coverage)
+		}
+		return "tests passed"
+	case StatusFailed:
+		if len(r.FailedTests) > 0 {
+			return fmt.Sprintf("%d test(s) failed: %s", len(r.FailedTests), strings.Join(r.FailedTests, ", "))
+		}
+		return fmt.Sprintf("tests failed with exit code %d", r.ExitCode)
+	case StatusCoverageBelow:
+		return fmt.Sprintf("coverage %.1f%% is below the %.1f%% threshold", *r.Coverage, r.CoverageThreshold)
+	default:
+		return "tests could not be run: " + r.Error
+	}
+}
+
+// Runner runs test commands for a project, in its sandbox
+type Runner interface {
+	// Run runs command in the project's work directory and returns its
+	// combined output and exit code; err is set when it could not run
+	Run(ctx context.Context, projectID, beadID, command string) (output string, exitCode int, err error)
+	// Framework names the project's test framework: go, jest, npm or pytest
+	Framework(projectID string) (string, error)
+}
+
+// Verifier runs verifications. Enabled reports false on a nil Verifier.
+type Verifier struct {
+	runner   Runner
+	defaults Config
+
+	mu       sync.RWMutex
+	projects map[string]Config
+}
+
+// NewVerifier creates a verifier that runs tests through runner
+func NewVerifier(runner Runner, defaults Config) *Verifier {
+	if runner == nil {
+		return nil
+	}
+	return &Verifier{runner: runner, defaults: defaults, projects: make(map[string]Config)}
+}
+
+// SetProjectConfig replaces the defaults for a single project
+func (v *Verifier) SetProjectConfig(projectID string, cfg Config) error {
+	if cfg.CoverageThreshold < 0 || cfg.CoverageThreshold > 100 {
+		return fmt.Errorf("coverage threshold must be between 0 and 100")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.projects[projectID] = cfg
+	return nil
+}
+
+// ForProject returns the effective configuration for a project
+func (v *Verifier) ForProject(projectID string) Config {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	cfg := v.defaults
+	if override, ok := v.projects[projectID]; ok {
+		cfg = override
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig().Timeout
+	}
+	return cfg
+}
+
+// Enabled reports whether beads of beadType in a project are verified
+func (v *Verifier) Enabled(projectID, beadType string) bool {
+	if v == nil {
+		return false
+	}
+	cfg := v.ForProject(projectID)
+	return cfg.Enabled && (len(cfg.BeadTypes) == 0 || slices.Contains(cfg.BeadTypes, beadType))
+}
+
+// Verify runs a project's tests for a bead. The result is always
+// returned; a run that could not complete has status error.
+func (v *Verifier) Verify(ctx context.Context, projectID, beadID string) *Result {
+	cfg := v.ForProject(projectID)
+	res := &Result{
+		BeadID:            beadID,
+		ProjectID:         projectID,
+		Command:           cfg.Command,
+		CoverageThreshold: cfg.CoverageThreshold,
+		StartedAt:         time.Now().UTC(),
+	}
+	defer func() { res.FinishedAt = time.Now().UTC() }()
+
+	if res.Command == "" {
+		framework, err := v.runner.Framework(projectID)
+		if err != nil {
+			res.Status, res.Error = StatusError, err.Error()
+			return res
+		}
+		if res.Command, err = DefaultCommand(framework, cfg.CoverageThreshold > 0); err != nil {
+			res.Status, res.Error = StatusError, err.Error()
+			return res
+		}
+	}
+
+	cctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	output, exitCode, err := v.runner.Run(cctx, projectID, beadID, res.Command)
+	res.ExitCode = exitCode
+	res.Output = tail(output, maxOutputChars)
+	if cctx.Err() != nil {
+		res.Status, res.Error = StatusError, fmt.Sprintf("tests did not finish within %s", cfg.Timeout)
+		return res
+	}
+	if err != nil {
+		res.Status, res.Error = StatusError, err.Error()
+		return res
+	}
+
+	res.FailedTests = FailedTests(output)
+	res.Coverage = Coverage(output)
+	switch {
+	case exitCode != 0:
+		res.Status = StatusFailed
+	case cfg.CoverageThreshold > 0 && res.Coverage == nil:
+		res.Status, res.Error = StatusError, fmt.Sprintf("%s reported no coverage", res.Command)
+	case cfg.CoverageThreshold > 0 && *res.Coverage < cfg.CoverageThreshold:
+		res.Status = StatusCoverageBelow
+	default:
+		res.Status = StatusPassed
+		res.Passed = true
+	}
+	return res
+}
+
+// DefaultCommand returns the test command for a framework, measuring
+// coverage when asked to
+func DefaultCommand(framework string, coverage bool) (string, error) {
+	switch framework {
+	case "go":
+		if coverage {
+			return "go test -cover ./...", nil
+		}
+		return "go test ./...", nil
+	case "jest":
+		if coverage {
+			return "npm test -- --coverage", nil
+		}
+		return "npm test", nil
+	case "npm":
+		return "npm test", nil
+	case "pytest":
+		if coverage {
+			return "pytest --cov", nil
+		}
+		return "pytest", nil
+	default:
+		return "", fmt.Errorf("no test command for framework %q; set one in the verification config", framework)
+	}
+}
+
+var (
+	goFailRe       = regexp.MustCompile(`(?m)^\s*--- FAIL: (\S+)`)
+	pytestFailRe   = regexp.MustCompile(`(?m)^FAILED (\S+)`)
+	jestFailRe     = regexp.MustCompile(`(?m)^\s*(?:✕|✗) (.+?)(?: \(\d+ ?m?s\))?$`)
+	goTotalRe      = regexp.MustCompile(`(?m)^total:\s+\(statements\)\s+([\d.]+)%`)
+	pytestTotalRe  = regexp.MustCompile(`(?m)^TOTAL\s+.*?([\d.]+)%\s*$`)
+	jestTotalRe    = regexp.MustCompile(`(?m)^All files\s*\|\s*([\d.]+)`)
+	goPackageCovRe = regexp.MustCompile(`coverage: ([\d.]+)% of statements`)
+)
+
+// FailedTests returns the names of the failed tests reported by go test,
+// pytest or jest
+func FailedTests(output string) []string {
+	var failed []string
+	seen := make(map[string]bool)
+	for _, re := range []*regexp.Regexp{goFailRe, pytestFailRe, jestFailRe} {
+		for _, m := range re.FindAllStringSubmatch(output, -1) {
+			name := strings.TrimSpace(m[1])
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			failed = append(failed, name)
+			if len(failed) == maxFailedTests {
+				return failed
+			}
+		}
+	}
+	return failed
+}
+
+// Coverage returns the total coverage reported by go tool cover, pytest-cov
+// or jest, or, for go test -cover, the average of the packages' coverage.
+// It returns nil when the output reports none.
+func Coverage(output string) *float64 {
+	for _, re := range []*regexp.Regexp{goTotalRe, pytestTotalRe, jestTotalRe} {
+		if m := re.FindStringSubmatch(output); m != nil {
+			if pct, err := strconv.ParseFloat(m[1], 64); err == nil {
+				return &pct
+			}
+		}
+	}
+	matches := goPackageCovRe.FindAllStringSubmatch(output, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+	sum := 0.0
+	for _, m := range matches {
+		pct, _ := strconv.ParseFloat(m[1], 64)
+		sum += pct
+	}
+	avg := sum / float64(len(matches))
+	return &avg
+}
+
+// tail returns the last n bytes of s
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[len(s)-n:]
+}
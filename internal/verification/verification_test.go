@@ -0,0 +1,130 @@
+package verification
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// fakeRunner answers every command with a fixed output and exit code and
+// records the command it ran
+type fakeRunner struct {
+	framework string
+	output    string
+	exitCode  int
+	err       error
+	command   string
+}
+
+func (f *fakeRunner) Run(ctx context.Context, projectID, beadID, command string) (string, int, error) {
+	f.command = command
+	return f.output, f.exitCode, f.err
+}
+
+func (f *fakeRunner) Framework(projectID string) (string, error) {
+	if f.framework == "" {
+		return "", fmt.Errorf("could not detect test framework")
+	}
+	return f.framework, nil
+}
+
+const goOutput = `--- FAIL: TestLogin (0.00s)
+    login_test.go:12: expected 200, got 500
+FAIL
+FAIL	example.com/app/auth	0.012s
+ok  	example.com/app/api	0.020s	coverage: 80.0% of statements
+`
+
+func TestFailedTestsAndCoverage(t *testing.T) {
+	failed := FailedTests(goOutput + "FAILED tests/test_api.py::test_create - AssertionError\n  ✕ renders the form (12 ms)\n")
+	want := []string{"TestLogin", "tests/test_api.py::test_create", "renders the form"}
+	if len(failed) != len(want) {
+		t.Fatalf("FailedTests = %q, want %q", failed, want)
+	}
+	for i := range want {
+		if failed[i] != want[i] {
+			t.Errorf("FailedTests[%d] = %q, want %q", i, failed[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		output string
+		want   float64
+	}{
+		{"ok  a 0.1s coverage: 60.0% of statements\nok  b 0.1s coverage: 90.0% of statements\n", 75},
+		{"total:\t\t\t\t(statements)\t\t82.5%\n", 82.5},
+		{"Name    Stmts   Miss  Cover\nTOTAL     120     18    85%\n", 85},
+		{"All files |   91.3 |    80 |\n", 91.3},
+	}
+	for _, tt := range tests {
+		got := Coverage(tt.output)
+		if got == nil || *got != tt.want {
+			t.Errorf("Coverage(%q) = %v, want %v", tt.output, got, tt.want)
+		}
+	}
+	if Coverage("PASS\n") != nil {
+		t.Error("expected no coverage without a report")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	passing := "ok  \texample.com/app\t0.1s\tcoverage: 72.0% of statements\n"
+	tests := []struct {
+		name     string
+		cfg      Config
+		runner   *fakeRunner
+		status   string
+		command  string
+		failures int
+	}{
+		{"tests pass", Config{Enabled: true}, &fakeRunner{framework: "go", output: "ok\n"}, StatusPassed, "go test ./...", 0},
+		{"tests fail", Config{Enabled: true}, &fakeRunner{framework: "go", output: goOutput, exitCode: 1}, StatusFailed, "go test ./...", 1},
+		{"coverage met", Config{Enabled: true, CoverageThreshold: 70}, &fakeRunner{framework: "go", output: passing}, StatusPassed, "go test -cover ./...", 0},
+		{"coverage below", Config{Enabled: true, CoverageThreshold: 80}, &fakeRunner{framework: "go", output: passing}, StatusCoverageBelow, "go test -cover ./...", 0},
+		{"coverage missing", Config{Enabled: true, CoverageThreshold: 80}, &fakeRunner{framework: "go", output: "ok\n"}, StatusError, "go test -cover ./...", 0},
+		{"custom command", Config{Enabled: true, Command: "make check"}, &fakeRunner{output: "ok\n"}, StatusPassed, "make check", 0},
+		{"no framework", Config{Enabled: true}, &fakeRunner{}, StatusError, "", 0},
+		{"runner error", Config{Enabled: true}, &fakeRunner{framework: "pytest", err: fmt.Errorf("sandbox runtime docker not available")}, StatusError, "pytest", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := NewVerifier(tt.runner, tt.cfg).Verify(context.Background(), "proj-1", "bd-1")
+			if res.Status != tt.status || res.Passed != (tt.status == StatusPassed) {
+				t.Errorf("status = %s (passed %v), want %s: %s", res.Status, res.Passed, tt.status, res.Error)
+			}
+			if tt.runner.command != tt.command || (tt.command != "" && res.Command != tt.command) {
+				t.Errorf("ran %q, want %q", tt.runner.command, tt.command)
+			}
+			if len(res.FailedTests) != tt.failures {
+				t.Errorf("failed tests = %q", res.FailedTests)
+			}
+			if res.Summary() == "" || res.FinishedAt.IsZero() {
+				t.Errorf("incomplete result %+v", res)
+			}
+		})
+	}
+}
+
+func TestVerifier_ProjectOverridesAndBeadTypes(t *testing.T) {
+	var nilVerifier *Verifier
+	if nilVerifier.Enabled("proj-1", "task") {
+		t.Error("a nil verifier verifies nothing")
+	}
+
+	v := NewVerifier(&fakeRunner{}, Config{Enabled: true, BeadTypes: []string{"task", "bug"}})
+	if !v.Enabled("proj-1", "task") || v.Enabled("proj-1", "decision") {
+		t.Error("expected only the configured bead types to be verified")
+	}
+	if err := v.SetProjectConfig("proj-2", Config{}); err != nil {
+		t.Fatal(err)
+	}
+	if v.Enabled("proj-2", "task") {
+		t.Error("a project override replaces the defaults")
+	}
+	if v.ForProject("proj-2").Timeout != DefaultConfig().Timeout {
+		t.Error("an override without a timeout gets the default")
+	}
+	if err := v.SetProjectConfig("proj-3", Config{CoverageThreshold: 120}); err == nil {
+		t.Error("expected a threshold above 100 to be refused")
+	}
+}
@@ -0,0 +1,245 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// dispatchBudgetFraction is the share of the model's context window the
+	// prompt a dispatch starts with may take; the rest is left for the
+	// action loop's responses and results
+	dispatchBudgetFraction = 0.6
+
+	// minSectionTokens is the smallest useful remainder of a trimmed
+	// section; a section that would be cut below it is dropped instead
+	minSectionTokens = 64
+)
+
+// Sections of a dispatch prompt. The operating model and the task itself
+// are always sent in full.
+const (
+	sectionOperatingModel = "operating_model"
+	sectionTask           = "task"
+	sectionPersona        = "persona"
+	sectionProjectFacts   = "project_facts"
+	sectionHistory        = "bead_history"
+	sectionLessons        = "lessons"
+	sectionFiles          = "file_contents"
+)
+
+// trimOrder lists the sections that may be cut, lowest priority first
+var trimOrder = []string{sectionFiles, sectionLessons, sectionHistory, sectionProjectFacts, sectionPersona}
+
+// promptSection is one part of a dispatch prompt and its size in tokens
+type promptSection struct {
+	name   string
+	tokens int
+}
+
+// sectionCut records a section the planner trimmed or dropped
+type sectionCut struct {
+	name   string
+	tokens int // before the cut
+	kept   int // 0 when dropped
+}
+
+// budgetPlan is the number of tokens each section of a prompt may use
+type budgetPlan struct {
+	budget    int
+	used      int
+	allowance map[string]int
+	cuts      []sectionCut
+}
+
+// planBudget fits sections into budget tokens, cutting the lowest-priority
+// sections first. Sections not in trimOrder are never cut, so the plan can
+// still exceed the budget when they alone do not fit.
+func planBudget(budget int, sections []promptSection) *budgetPlan {
+	return planBudgetWithCaps(budget, sections, nil)
+}
+
+// planBudgetWithCaps is planBudget with a cap in tokens on some sections.
+// Sections over their cap are trimmed to it before anything else is cut.
+func planBudgetWithCaps(budget int, sections []promptSection, caps map[string]int) *budgetPlan {
+	plan := &budgetPlan{budget: budget, allowance: make(map[string]int, len(sections))}
+	for _, s := range sections {
+		plan.allowance[s.name] += s.tokens
+		plan.used += s.tokens
+	}
+
+	for _, name := range trimOrder {
+		if limit, ok := caps[name]; ok && plan.allowance[name] > limit {
+			plan.cut(name, limit)
+		}
+	}
+	for _, name := range trimOrder {
+		if plan.used <= budget {
+			break
+		}
+		tokens := plan.allowance[name]
+		if tokens == 0 {
+			continue
+		}
+		plan.cut(name, tokens-(plan.used-budget))
+	}
+	return plan
+}
+
+// cut lowers a section's allowance to keep tokens, or drops it when fewer
+// than minSectionTokens would be left
+func (p *budgetPlan) cut(name string, keep int) {
+	if keep < minSectionTokens {
+		keep = 0
+	}
+	tokens := p.allowance[name]
+	p.allowance[name] = keep
+	p.used -= tokens - keep
+	for i := range p.cuts {
+		if p.cuts[i].name == name {
+			p.cuts[i].kept = keep
+			return
+		}
+	}
+	p.cuts = append(p.cuts, sectionCut{name: name, tokens: tokens, kept: keep})
+}
+
+// String describes what the plan cut, e.g. for the dispatch log
+func (p *budgetPlan) String() string {
+	if len(p.cuts) == 0 {
+		return "nothing cut"
+	}
+	parts := make([]string, len(p.cuts))
+	for i, c := range p.cuts {
+		if c.kept == 0 {
+			parts[i] = fmt.Sprintf("dropped %s (%d tokens)", c.name, c.tokens)
+		} else {
+			parts[i] = fmt.Sprintf("trimmed %s from %d to %d tokens", c.name, c.tokens, c.kept)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// trimText cuts s to about maxTokens, preferring to end at a line break
+func trimText(s string, maxTokens int) string {
+	if estimateTokens(s) <= maxTokens {
+		return s
+	}
+	if maxTokens <= 0 {
+		return ""
+	}
+	cut := maxTokens * 4
+	if nl := strings.LastIndexByte(s[:cut], '\n'); nl > cut*3/4 {
+		cut = nl
+	}
+	return s[:cut] + "\n... (trimmed to fit the prompt budget)"
+}
+
+// buildDispatchMessages assembles the messages a dispatch starts with:
+// the system prompt, the bead's earlier history, and the task. Sections
+// are fitted to the model's context window by planBudget, and a system
+// prompt built here is stored with a new conversation. It also returns
+// the IDs of the stored lessons in the system prompt.
+func (w *Worker) buildDispatchMessages(config *LoopConfig, task *Task, conversationCtx *models.ConversationContext) ([]provider.ChatMessage, []string) {
+	var operatingModel, persona, lessons string
+	var lessonIDs []string
+	var history []provider.ChatMessage
+	historyTokens := 0
+
+	storedPrompt := conversationCtx != nil && len(conversationCtx.Messages) > 0
+	if storedPrompt {
+		// Later dispatches keep the system prompt of the first one
+		history = w.compressConversation(conversationCtx)
+		operatingModel = history[0].Content
+		lessonIDs = storedLessonIDs(conversationCtx)
+		for _, m := range history[1:] {
+			historyTokens += estimateTokens(m.Content)
+		}
+	} else {
+		lessons, lessonIDs = w.lessonsForPrompt(config.LessonsProvider, task.ProjectID, task.Context)
+		operatingModel = w.operatingModelPrompt("", "")
+		persona = w.personaPrompt()
+	}
+
+	var files []string
+	if task.Files != "" {
+		files = append(files, task.Files)
+	}
+	if config.FileExpertise != nil && w.agent != nil {
+		if expertise := config.FileExpertise.GetExpertiseForPrompt(task.ProjectID, w.agent.ID); expertise != "" {
+			files = append(files, expertise)
+		}
+	}
+	fileContents := strings.Join(files, "\n")
+
+	// A new system prompt repeats the task context as progress context
+	projectCopies := 1
+	if !storedPrompt {
+		projectCopies = 2
+	}
+
+	budget := w.contextBudget()
+	model := w.provider.Config.Model
+	promptBudget := int(float64(w.getModelTokenLimit()) * budget.promptShare(model))
+	plan := planBudgetWithCaps(promptBudget, []promptSection{
+		{sectionOperatingModel, estimateTokens(operatingModel)},
+		{sectionPersona, estimateTokens(persona)},
+		{sectionLessons, estimateTokens(lessons)},
+		{sectionHistory, historyTokens},
+		{sectionTask, estimateTokens(task.Description)},
+		{sectionProjectFacts, estimateTokens(task.Context) * projectCopies},
+		{sectionFiles, estimateTokens(fileContents)},
+	}, budget.sectionCaps(model, promptBudget))
+	if len(plan.cuts) > 0 {
+		log.Printf("[PromptBudget] Task %s: %s (budget %d tokens, prompt %d tokens)",
+			task.ID, plan, plan.budget, plan.used)
+		budget.record(model, plan)
+	}
+
+	projectFacts := trimText(task.Context, plan.allowance[sectionProjectFacts]/projectCopies)
+	fileContents = trimText(fileContents, plan.allowance[sectionFiles])
+
+	var messages []provider.ChatMessage
+	if storedPrompt {
+		if allowed := plan.allowance[sectionHistory]; allowed < historyTokens {
+			history = w.shrinkHistory(conversationCtx, allowed, history[0])
+		}
+		messages = append(messages, history...)
+	} else {
+		if plan.allowance[sectionLessons] == 0 {
+			lessonIDs = nil
+		}
+		systemPrompt := w.operatingModelPrompt(trimText(lessons, plan.allowance[sectionLessons]), projectFacts) +
+			trimText(persona, plan.allowance[sectionPersona])
+		if conversationCtx != nil {
+			conversationCtx.AddMessage("system", systemPrompt, len(systemPrompt)/4)
+			storeLessonIDs(conversationCtx, lessonIDs)
+		}
+		messages = append(messages, provider.ChatMessage{Role: "system", Content: systemPrompt})
+	}
+
+	userPrompt := task.Description
+	if projectFacts != "" {
+		userPrompt = fmt.Sprintf("%s\n\nContext:\n%s", userPrompt, projectFacts)
+	}
+	if fileContents != "" {
+		userPrompt += "\n\n" + fileContents
+	}
+	return append(messages, provider.ChatMessage{Role: "user", Content: userPrompt}), lessonIDs
+}
+
+// shrinkHistory recompresses a bead's history into allowed tokens beyond
+// its system prompt, keeping only the system prompt when nothing fits
+func (w *Worker) shrinkHistory(c *models.ConversationContext, allowed int, system provider.ChatMessage) []provider.ChatMessage {
+	if allowed == 0 {
+		return []provider.ChatMessage{system, {
+			Role:    "system",
+			Content: "[Note: earlier dispatches of this bead omitted to fit the prompt budget]",
+		}}
+	}
+	return compressHistory(c.Messages, dispatchBoundaries(c), allowed+estimateTokens(system.Content))
+}
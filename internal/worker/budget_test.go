@@ -0,0 +1,256 @@
+package worker
+
+import (
+	"strings"
+	"testing"
+
+	internalmodels "github.com/jordanhubbard/loom/internal/models"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestPlanBudget_FitsUnchanged(t *testing.T) {
+	plan := planBudget(1000, []promptSection{
+		{sectionOperatingModel, 400},
+		{sectionLessons, 300},
+		{sectionFiles, 200},
+	})
+	if len(plan.cuts) != 0 {
+		t.Errorf("expected no cuts, got %s", plan)
+	}
+	if plan.allowance[sectionLessons] != 300 || plan.used != 900 {
+		t.Errorf("unexpected plan: %+v", plan)
+	}
+}
+
+func TestPlanBudget_TrimsLowestPriorityFirst(t *testing.T) {
+	plan := planBudget(1000, []promptSection{
+		{sectionOperatingModel, 500},
+		{sectionPersona, 100},
+		{sectionHistory, 300},
+		{sectionLessons, 200},
+		{sectionFiles, 400},
+	})
+
+	// 1500 tokens: files (lowest priority) drop entirely, lessons are
+	// trimmed to the remaining 100 tokens over, history is untouched
+	if plan.allowance[sectionFiles] != 0 {
+		t.Errorf("expected files dropped, got %d tokens", plan.allowance[sectionFiles])
+	}
+	if plan.allowance[sectionLessons] != 100 {
+		t.Errorf("expected lessons trimmed to 100 tokens, got %d", plan.allowance[sectionLessons])
+	}
+	if plan.allowance[sectionHistory] != 300 || plan.allowance[sectionPersona] != 100 {
+		t.Errorf("higher-priority sections should be kept: %+v", plan.allowance)
+	}
+	if plan.used != 1000 {
+		t.Errorf("expected plan to use the whole budget, used %d", plan.used)
+	}
+	want := "dropped file_contents (400 tokens), trimmed lessons from 200 to 100 tokens"
+	if plan.String() != want {
+		t.Errorf("log = %q, want %q", plan.String(), want)
+	}
+}
+
+func TestPlanBudget_DropsTinyRemainders(t *testing.T) {
+	// Cutting lessons to fit would leave fewer than minSectionTokens
+	plan := planBudget(950, []promptSection{
+		{sectionTask, 900},
+		{sectionLessons, 50 + minSectionTokens},
+	})
+	if plan.allowance[sectionLessons] != 0 {
+		t.Errorf("expected lessons dropped rather than cut to a stub, got %d tokens", plan.allowance[sectionLessons])
+	}
+}
+
+func TestPlanBudget_NeverCutsRequiredSections(t *testing.T) {
+	plan := planBudget(100, []promptSection{
+		{sectionOperatingModel, 300},
+		{sectionTask, 200},
+		{sectionPersona, 50},
+	})
+	if plan.allowance[sectionOperatingModel] != 300 || plan.allowance[sectionTask] != 200 {
+		t.Errorf("operating model and task must be kept: %+v", plan.allowance)
+	}
+	if plan.allowance[sectionPersona] != 0 {
+		t.Errorf("expected persona dropped, got %d", plan.allowance[sectionPersona])
+	}
+	if plan.used <= plan.budget {
+		t.Errorf("plan should report it is still over budget, used %d of %d", plan.used, plan.budget)
+	}
+}
+
+func TestPlanBudgetWithCaps_CapsBeforeTrimming(t *testing.T) {
+	plan := planBudgetWithCaps(750, []promptSection{
+		{sectionOperatingModel, 300},
+		{sectionHistory, 500},
+		{sectionLessons, 100},
+		{sectionFiles, 300},
+	}, map[string]int{sectionHistory: 250})
+
+	// History is capped even though files are lower priority; the 200
+	// tokens still over then come out of files
+	if plan.allowance[sectionHistory] != 250 || plan.allowance[sectionFiles] != 100 || plan.allowance[sectionLessons] != 100 {
+		t.Errorf("unexpected allowances: %+v", plan.allowance)
+	}
+	if plan.used != 750 {
+		t.Errorf("expected 750 tokens used, got %d", plan.used)
+	}
+
+	// A capped section cut again is reported once
+	plan = planBudgetWithCaps(300, []promptSection{
+		{sectionOperatingModel, 100},
+		{sectionFiles, 500},
+	}, map[string]int{sectionFiles: 400})
+	if len(plan.cuts) != 1 || plan.cuts[0].tokens != 500 || plan.cuts[0].kept != 200 {
+		t.Errorf("expected one cut of files from 500 to 200 tokens, got %s", plan)
+	}
+}
+
+type stubMetadata map[string]internalmodels.ModelMetadata
+
+func (s stubMetadata) ModelMetadata(model string) (internalmodels.ModelMetadata, bool) {
+	md, ok := s[model]
+	return md, ok
+}
+
+type recordedCut struct {
+	model, section string
+	tokens, kept   int
+}
+
+type cutRecorder struct{ cuts []recordedCut }
+
+func (r *cutRecorder) RecordPromptCut(model, section string, tokens, kept int) {
+	r.cuts = append(r.cuts, recordedCut{model, section, tokens, kept})
+}
+
+func TestContextBudget_ContextWindow(t *testing.T) {
+	b := &ContextBudget{Metadata: stubMetadata{"big": {Model: "big", ContextWindow: 200000}}, DefaultWindow: 8192}
+	if got := b.contextWindow(&provider.ProviderConfig{Model: "big", ContextWindow: 65536}); got != 65536 {
+		t.Errorf("expected the provider's discovered window, got %d", got)
+	}
+	if got := b.contextWindow(&provider.ProviderConfig{Model: "big"}); got != 200000 {
+		t.Errorf("expected the model's metadata window, got %d", got)
+	}
+	if got := b.contextWindow(&provider.ProviderConfig{Model: "unknown"}); got != 8192 {
+		t.Errorf("expected the configured default, got %d", got)
+	}
+	var none *ContextBudget
+	if got := none.contextWindow(&provider.ProviderConfig{Model: "big"}); got != defaultContextWindow {
+		t.Errorf("expected the built-in default without a budget, got %d", got)
+	}
+}
+
+func TestBuildDispatchMessages_PerModelBudget(t *testing.T) {
+	w := makeTestWorker(nil)
+	w.provider.Config.Model = "small"
+	w.provider.Config.ContextWindow = 10000
+	rec := &cutRecorder{}
+	w.SetContextBudget(&ContextBudget{
+		SectionShares: map[string]float64{sectionFiles: 0.5},
+		Models: map[string]ModelBudget{
+			"small": {PromptShare: 0.5, SectionShares: map[string]float64{sectionFiles: 0.1}},
+		},
+		Telemetry: rec,
+	})
+
+	// The model's 10% cap of a 5000-token prompt budget applies, not the
+	// default 50%
+	task := &Task{ID: "t1", Description: "Fix it", Files: strings.Repeat("src/pkg/file.go\n", 1000), ProjectID: "proj-1"}
+	msgs, _ := w.buildDispatchMessages(&LoopConfig{}, task, nil)
+	if tokens := estimateTokens(msgs[1].Content); tokens > 600 {
+		t.Errorf("expected file contents capped near 500 tokens, prompt has %d", tokens)
+	}
+	if len(rec.cuts) != 1 || rec.cuts[0].model != "small" || rec.cuts[0].section != sectionFiles || rec.cuts[0].kept != 500 {
+		t.Errorf("expected the files cut reported, got %+v", rec.cuts)
+	}
+}
+
+func TestTrimText(t *testing.T) {
+	text := strings.Repeat("line of file content\n", 100)
+	if got := trimText(text, 10_000); got != text {
+		t.Error("text within budget should be unchanged")
+	}
+	got := trimText(text, 100)
+	if estimateTokens(got) > 120 || !strings.Contains(got, "trimmed to fit the prompt budget") {
+		t.Errorf("unexpected trim (%d tokens): %q", estimateTokens(got), got[len(got)-60:])
+	}
+	if !strings.HasSuffix(strings.TrimSuffix(got, "\n... (trimmed to fit the prompt budget)"), "content") {
+		t.Error("trim should end at a line break")
+	}
+	if trimText(text, 0) != "" {
+		t.Error("zero allowance should drop the text")
+	}
+}
+
+type stubExpertise struct{ text string }
+
+func (s stubExpertise) GetExpertiseForPrompt(projectID, agentID string) string { return s.text }
+func (s stubExpertise) RecordFileEdits(projectID, agentID, personaName, beadID string, paths []string) error {
+	return nil
+}
+
+func TestBuildDispatchMessages_DropsFilesBeforeLessons(t *testing.T) {
+	w := makeTestWorker(&models.Persona{Character: "Expert coder"})
+	lessons := strings.Repeat("Lesson: run the tests before pushing.\n", 20)
+	overview := strings.Repeat("src/pkg/file.go\n", 2000)
+	task := &Task{ID: "t1", Description: "Fix the login bug", Context: "Project: loom", Files: overview, ProjectID: "proj-1"}
+	config := &LoopConfig{
+		LessonsProvider: &mockLessonsProvider{lessonsText: lessons},
+		FileExpertise:   stubExpertise{text: "# Files You Have Worked On\n- auth.go (3 successful edits)\n"},
+	}
+
+	// Leave room for everything but the repository overview
+	fixed := estimateTokens(w.operatingModelPrompt(lessons, task.Context)) + estimateTokens(w.personaPrompt()) +
+		estimateTokens(task.Description) + estimateTokens(task.Context)
+	w.provider.Config.ContextWindow = int(float64(fixed+200) / dispatchBudgetFraction)
+
+	msgs, _ := w.buildDispatchMessages(config, task, nil)
+	if len(msgs) != 2 || msgs[0].Role != "system" || msgs[1].Role != "user" {
+		t.Fatalf("expected system and user messages, got %d", len(msgs))
+	}
+	if !strings.Contains(msgs[0].Content, "Lesson: run the tests") || !strings.Contains(msgs[0].Content, "Expert coder") {
+		t.Error("lessons and persona should be kept")
+	}
+	user := msgs[1].Content
+	if !strings.Contains(user, "Fix the login bug") || !strings.Contains(user, "Project: loom") {
+		t.Error("task and project facts should be kept")
+	}
+	if !strings.Contains(user, "trimmed to fit the prompt budget") || len(user) > len(overview)/2 {
+		t.Errorf("file contents should be trimmed, user prompt is %d chars", len(user))
+	}
+}
+
+func TestBuildDispatchMessages_StoresSystemPromptWithConversation(t *testing.T) {
+	w := makeTestWorker(nil)
+	c := models.NewConversationContext("s1", "bead-1", "proj-1", 0)
+	task := &Task{ID: "t1", Description: "Do the thing", ProjectID: "proj-1"}
+
+	first, _ := w.buildDispatchMessages(&LoopConfig{}, task, c)
+	if len(c.Messages) != 1 || c.Messages[0].Content != first[0].Content {
+		t.Fatal("system prompt should be stored with a new conversation")
+	}
+
+	// A later dispatch reuses the stored prompt and carries the history
+	markDispatchStart(c)
+	c.AddMessage("assistant", `{"action":"done"}`, 4)
+	second, _ := w.buildDispatchMessages(&LoopConfig{}, task, c)
+	if len(second) != 3 || second[0].Content != first[0].Content || second[1].Role != "assistant" {
+		t.Errorf("unexpected messages for second dispatch: %+v", second)
+	}
+}
+
+func TestBuildDispatchMessages_DropsHistoryOverBudget(t *testing.T) {
+	w := makeTestWorker(nil)
+	c := buildDispatchHistory(6)
+	task := &Task{ID: "t1", Description: "Continue", ProjectID: "proj-1"}
+
+	// Budget covers the stored system prompt and task but no history
+	w.provider.Config.ContextWindow = int(float64(estimateTokens(c.Messages[0].Content)+estimateTokens(task.Description)+10) / dispatchBudgetFraction)
+
+	msgs, _ := w.buildDispatchMessages(&LoopConfig{}, task, c)
+	if len(msgs) != 3 || !strings.Contains(msgs[1].Content, "omitted to fit the prompt budget") {
+		t.Fatalf("expected history to be replaced by a note, got %d messages", len(msgs))
+	}
+}
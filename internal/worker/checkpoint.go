@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// DefaultMaxResumes is the number of times a bead's action loop may be
+// resumed from a checkpoint before it is restarted from scratch.
+const DefaultMaxResumes = 3
+
+// resumableTerminalReasons are the loop outcomes that keep the checkpoint so
+// the next dispatch can pick up where this one stopped. A canceled context
+// is what an in-flight loop sees when the server shuts down.
+var resumableTerminalReasons = map[string]bool{
+	"context_canceled": true,
+	"error":            true,
+}
+
+// loopCheckpoint is the restored state of an interrupted action loop.
+type loopCheckpoint struct {
+	Iteration   int
+	TokensUsed  int
+	Messages    []provider.ChatMessage
+	ActionLog   []ActionLogEntry
+	ResumeCount int
+}
+
+// loadCheckpoint returns the checkpoint for the task's bead if one exists and
+// has not exhausted its resume budget. Exhausted or unreadable checkpoints
+// are discarded so the loop starts over.
+func (w *Worker) loadCheckpoint(config *LoopConfig, task *Task) *loopCheckpoint {
+	if config.DB == nil || task.BeadID == "" {
+		return nil
+	}
+
+	cp, err := config.DB.GetDispatchCheckpoint(task.BeadID)
+	if err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to load checkpoint for bead %s: %v", task.BeadID, err)
+		return nil
+	}
+	if cp == nil {
+		return nil
+	}
+
+	maxResumes := config.MaxResumes
+	if maxResumes <= 0 {
+		maxResumes = DefaultMaxResumes
+	}
+	if cp.ResumeCount >= maxResumes {
+		log.Printf("[ActionLoop] Checkpoint for bead %s already resumed %d times, starting over", task.BeadID, cp.ResumeCount)
+		w.deleteCheckpoint(config, task.BeadID)
+		return nil
+	}
+
+	restored := &loopCheckpoint{
+		Iteration:   cp.Iteration,
+		TokensUsed:  cp.TokensUsed,
+		ResumeCount: cp.ResumeCount + 1,
+	}
+	if err := json.Unmarshal([]byte(cp.MessagesJSON), &restored.Messages); err != nil || len(restored.Messages) == 0 {
+		log.Printf("[ActionLoop] Discarding unreadable checkpoint for bead %s: %v", task.BeadID, err)
+		w.deleteCheckpoint(config, task.BeadID)
+		return nil
+	}
+	if err := json.Unmarshal([]byte(cp.ActionLogJSON), &restored.ActionLog); err != nil {
+		log.Printf("[ActionLoop] Discarding unreadable checkpoint for bead %s: %v", task.BeadID, err)
+		w.deleteCheckpoint(config, task.BeadID)
+		return nil
+	}
+
+	return restored
+}
+
+// saveCheckpoint persists the loop state after a completed iteration.
+func (w *Worker) saveCheckpoint(config *LoopConfig, task *Task, iteration, resumeCount int, messages []provider.ChatMessage, loopResult *LoopResult) {
+	if config.DB == nil || task.BeadID == "" {
+		return
+	}
+
+	messagesJSON, err := json.Marshal(messages)
+	if err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to marshal checkpoint messages: %v", err)
+		return
+	}
+	actionLogJSON, err := json.Marshal(loopResult.ActionLog)
+	if err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to marshal checkpoint action log: %v", err)
+		return
+	}
+
+	agentID := ""
+	if w.agent != nil {
+		agentID = w.agent.ID
+	}
+
+	cp := &database.DispatchCheckpoint{
+		BeadID:        task.BeadID,
+		ProjectID:     task.ProjectID,
+		AgentID:       agentID,
+		TaskID:        task.ID,
+		Iteration:     iteration,
+		TokensUsed:    loopResult.TokensUsed,
+		MessagesJSON:  string(messagesJSON),
+		ActionLogJSON: string(actionLogJSON),
+		ResumeCount:   resumeCount,
+	}
+	if err := config.DB.SaveDispatchCheckpoint(cp); err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to save checkpoint for bead %s: %v", task.BeadID, err)
+	}
+}
+
+// finishCheckpoint drops the checkpoint once the loop reached an outcome
+// that should not be resumed.
+func (w *Worker) finishCheckpoint(config *LoopConfig, task *Task, loopResult *LoopResult) {
+	if config.DB == nil || task.BeadID == "" {
+		return
+	}
+	if resumableTerminalReasons[loopResult.TerminalReason] {
+		return
+	}
+	w.deleteCheckpoint(config, task.BeadID)
+}
+
+func (w *Worker) deleteCheckpoint(config *LoopConfig, beadID string) {
+	if err := config.DB.DeleteDispatchCheckpoint(beadID); err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to delete checkpoint for bead %s: %v", beadID, err)
+	}
+}
+
+// resumeNotice is appended to a restored conversation so the model knows the
+// loop was interrupted and should continue rather than start over.
+func resumeNotice(iteration int) string {
+	return fmt.Sprintf("## Resumed\n\nThis session was interrupted after iteration %d and has been resumed from a checkpoint. "+
+		"Your previous actions and their results are above. Continue from where you left off; do not repeat completed work.", iteration)
+}
+
+// replayActionLog rebuilds the accumulated action results and progress
+// tracker state from a restored action log.
+func replayActionLog(entries []ActionLogEntry, tracker *ProgressTracker) []actions.Result {
+	var all []actions.Result
+	for _, entry := range entries {
+		all = append(all, entry.Results...)
+		tracker.Update(entry.Iteration, entry.Results)
+	}
+	return all
+}
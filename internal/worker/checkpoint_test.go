@@ -0,0 +1,105 @@
+package worker
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/database"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func newCheckpointTestDB(t *testing.T) *database.Database {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("Failed to create database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestCheckpoint_SaveAndLoad(t *testing.T) {
+	db := newCheckpointTestDB(t)
+	w := &Worker{id: "w1", agent: &models.Agent{ID: "agent-1"}}
+	config := &LoopConfig{DB: db}
+	task := &Task{ID: "task-1", BeadID: "bead-1", ProjectID: "proj-1"}
+
+	if cp := w.loadCheckpoint(config, task); cp != nil {
+		t.Fatalf("expected no checkpoint, got %+v", cp)
+	}
+
+	messages := []provider.ChatMessage{
+		{Role: "system", Content: "sys"},
+		{Role: "user", Content: "do it"},
+		{Role: "assistant", Content: `{"action":"read_file","path":"main.go"}`},
+	}
+	loopResult := &LoopResult{
+		TaskResult: &TaskResult{TokensUsed: 420},
+		ActionLog: []ActionLogEntry{{
+			Iteration: 1,
+			Actions:   []actions.Action{{Type: actions.ActionReadFile, Path: "main.go"}},
+			Results:   []actions.Result{{ActionType: actions.ActionReadFile, Status: "executed"}},
+		}},
+	}
+	w.saveCheckpoint(config, task, 1, 0, messages, loopResult)
+
+	cp := w.loadCheckpoint(config, task)
+	if cp == nil {
+		t.Fatal("expected checkpoint after save")
+	}
+	if cp.Iteration != 1 || cp.TokensUsed != 420 || cp.ResumeCount != 1 {
+		t.Errorf("unexpected checkpoint state: %+v", cp)
+	}
+	if len(cp.Messages) != 3 || cp.Messages[2].Role != "assistant" {
+		t.Errorf("messages not restored: %+v", cp.Messages)
+	}
+	if len(cp.ActionLog) != 1 || cp.ActionLog[0].Actions[0].Path != "main.go" {
+		t.Errorf("action log not restored: %+v", cp.ActionLog)
+	}
+
+	tracker := NewProgressTracker(10)
+	if all := replayActionLog(cp.ActionLog, tracker); len(all) != 1 {
+		t.Errorf("replayActionLog returned %d results, want 1", len(all))
+	}
+}
+
+func TestCheckpoint_MaxResumes(t *testing.T) {
+	db := newCheckpointTestDB(t)
+	w := &Worker{id: "w1", agent: &models.Agent{ID: "agent-1"}}
+	config := &LoopConfig{DB: db, MaxResumes: 2}
+	task := &Task{ID: "task-1", BeadID: "bead-1", ProjectID: "proj-1"}
+
+	messages := []provider.ChatMessage{{Role: "system", Content: "sys"}}
+	loopResult := &LoopResult{TaskResult: &TaskResult{}}
+	w.saveCheckpoint(config, task, 3, 2, messages, loopResult)
+
+	if cp := w.loadCheckpoint(config, task); cp != nil {
+		t.Fatalf("expected exhausted checkpoint to be discarded, got %+v", cp)
+	}
+	if stored, _ := db.GetDispatchCheckpoint("bead-1"); stored != nil {
+		t.Error("expected exhausted checkpoint to be deleted")
+	}
+}
+
+func TestCheckpoint_FinishKeepsResumable(t *testing.T) {
+	db := newCheckpointTestDB(t)
+	w := &Worker{id: "w1", agent: &models.Agent{ID: "agent-1"}}
+	config := &LoopConfig{DB: db}
+	task := &Task{ID: "task-1", BeadID: "bead-1", ProjectID: "proj-1"}
+	messages := []provider.ChatMessage{{Role: "system", Content: "sys"}}
+
+	loopResult := &LoopResult{TaskResult: &TaskResult{}, TerminalReason: "context_canceled"}
+	w.saveCheckpoint(config, task, 2, 0, messages, loopResult)
+	w.finishCheckpoint(config, task, loopResult)
+	if stored, _ := db.GetDispatchCheckpoint("bead-1"); stored == nil {
+		t.Fatal("expected checkpoint to survive a canceled loop")
+	}
+
+	loopResult.TerminalReason = "completed"
+	w.finishCheckpoint(config, task, loopResult)
+	if stored, _ := db.GetDispatchCheckpoint("bead-1"); stored != nil {
+		t.Error("expected checkpoint to be deleted after completion")
+	}
+}
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// defaultContextWindow is assumed for models whose context window is not
+// known from the provider or model metadata
+const defaultContextWindow = 32768
+
+// BudgetTelemetry receives each cut the prompt budget planner makes:
+// tokens is the section's size before the cut and kept is what was left of
+// it, 0 when the section was dropped
+type BudgetTelemetry interface {
+	RecordPromptCut(model, section string, tokens, kept int)
+}
+
+// ModelBudget overrides how a model's prompt budget is divided
+type ModelBudget struct {
+	PromptShare   float64            // Share of the context window the starting prompt may take
+	SectionShares map[string]float64 // Caps on sections as shares of the prompt budget
+}
+
+// ContextBudget sizes the prompts workers build for each model. The
+// context window comes from the provider, then the model's metadata, then
+// DefaultWindow; the prompt may take PromptShare of it, and sections with a
+// share in SectionShares are capped at that share of the prompt budget
+// before lower-priority sections are cut. Models overrides both by model.
+type ContextBudget struct {
+	Metadata      provider.ModelMetadataSource
+	DefaultWindow int
+	PromptShare   float64
+	SectionShares map[string]float64
+	Models        map[string]ModelBudget
+	Telemetry     BudgetTelemetry
+}
+
+// PromptSections lists the prompt sections a ContextBudget may cap, lowest
+// priority first
+func PromptSections() []string {
+	return append([]string(nil), trimOrder...)
+}
+
+// IsPromptSection reports whether name is a section a ContextBudget may cap
+func IsPromptSection(name string) bool {
+	return containsString(trimOrder, name)
+}
+
+// contextWindow returns the context window of a provider's model
+func (b *ContextBudget) contextWindow(cfg *provider.ProviderConfig) int {
+	if cfg != nil && cfg.ContextWindow > 0 {
+		return cfg.ContextWindow
+	}
+	if b != nil && b.Metadata != nil && cfg != nil {
+		if md, ok := b.Metadata.ModelMetadata(cfg.Model); ok && md.ContextWindow > 0 {
+			return md.ContextWindow
+		}
+	}
+	if b != nil && b.DefaultWindow > 0 {
+		return b.DefaultWindow
+	}
+	return defaultContextWindow
+}
+
+// promptShare returns the share of the context window a model's starting
+// prompt may take
+func (b *ContextBudget) promptShare(model string) float64 {
+	if b == nil {
+		return dispatchBudgetFraction
+	}
+	if mb, ok := b.Models[model]; ok && mb.PromptShare > 0 && mb.PromptShare <= 1 {
+		return mb.PromptShare
+	}
+	if b.PromptShare > 0 && b.PromptShare <= 1 {
+		return b.PromptShare
+	}
+	return dispatchBudgetFraction
+}
+
+// sectionCaps converts a model's section shares into token caps within a
+// prompt budget. A model's shares replace the default ones section by
+// section.
+func (b *ContextBudget) sectionCaps(model string, budget int) map[string]int {
+	if b == nil {
+		return nil
+	}
+	shares := make(map[string]float64, len(b.SectionShares))
+	for name, share := range b.SectionShares {
+		shares[name] = share
+	}
+	for name, share := range b.Models[model].SectionShares {
+		shares[name] = share
+	}
+	if len(shares) == 0 {
+		return nil
+	}
+	caps := make(map[string]int, len(shares))
+	for name, share := range shares {
+		if share > 0 && share < 1 {
+			caps[name] = int(float64(budget) * share)
+		}
+	}
+	return caps
+}
+
+// record reports a plan's cuts to the telemetry
+func (b *ContextBudget) record(model string, plan *budgetPlan) {
+	if b == nil || b.Telemetry == nil {
+		return
+	}
+	for _, c := range plan.cuts {
+		b.Telemetry.RecordPromptCut(model, c.name, c.tokens, c.kept)
+	}
+}
@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// Guidance is a comment a human posted on a bead while its agent worked
+type Guidance struct {
+	CommentID string    `json:"comment_id"`
+	Author    string    `json:"author"`
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GuidanceProvider supplies the comments posted on a bead during its
+// action loop and records that the agent took them in.
+type GuidanceProvider interface {
+	// PendingGuidance returns the bead's comments posted after since,
+	// oldest first
+	PendingGuidance(beadID string, since time.Time) ([]Guidance, error)
+	// AcknowledgeGuidance records that an agent was given guidance in an
+	// iteration of its loop
+	AcknowledgeGuidance(projectID, beadID, agentID string, iteration int, guidance []Guidance)
+}
+
+// guidanceInbox tracks which of a bead's comments a loop has injected
+type guidanceInbox struct {
+	since time.Time
+	seen  map[string]bool
+}
+
+func newGuidanceInbox(since time.Time) *guidanceInbox {
+	return &guidanceInbox{since: since, seen: make(map[string]bool)}
+}
+
+// take returns the comments not injected yet
+func (in *guidanceInbox) take(gp GuidanceProvider, beadID string) []Guidance {
+	pending, err := gp.PendingGuidance(beadID, in.since)
+	if err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to check bead %s for guidance: %v", beadID, err)
+		return nil
+	}
+	var fresh []Guidance
+	for _, g := range pending {
+		if in.seen[g.CommentID] {
+			continue
+		}
+		in.seen[g.CommentID] = true
+		fresh = append(fresh, g)
+	}
+	return fresh
+}
+
+// injectGuidance adds the comments posted on the bead since the loop's
+// last check to the conversation before its next call to the model, and
+// records them in the action log of the iteration about to run
+func (w *Worker) injectGuidance(config *LoopConfig, task *Task, in *guidanceInbox, iteration int, messages []provider.ChatMessage, conversationCtx *models.ConversationContext, loopResult *LoopResult) []provider.ChatMessage {
+	if config.Guidance == nil || task.BeadID == "" {
+		return messages
+	}
+	guidance := in.take(config.Guidance, task.BeadID)
+	if len(guidance) == 0 {
+		return messages
+	}
+
+	msg := formatGuidance(guidance)
+	messages = append(messages, provider.ChatMessage{Role: "user", Content: msg})
+	if conversationCtx != nil {
+		conversationCtx.AddMessage("user", msg, len(msg)/4)
+	}
+	loopResult.ActionLog = append(loopResult.ActionLog, ActionLogEntry{
+		Iteration: iteration,
+		Guidance:  guidance,
+		Timestamp: time.Now(),
+	})
+	config.Guidance.AcknowledgeGuidance(task.ProjectID, task.BeadID, w.agent.ID, iteration, guidance)
+	log.Printf("[ActionLoop] Injected %d comment(s) of guidance into iteration %d of bead %s", len(guidance), iteration, task.BeadID)
+	return messages
+}
+
+// formatGuidance renders comments as a message from the humans watching
+// the bead
+func formatGuidance(guidance []Guidance) string {
+	var sb strings.Builder
+	sb.WriteString("## Guidance From a Human\n\n")
+	sb.WriteString("While you were working, a human commented on this bead. ")
+	sb.WriteString("Take their guidance into account from your next action on; it overrides your earlier plan where they conflict.\n\n")
+	for _, g := range guidance {
+		author := g.Author
+		if author == "" {
+			author = "someone"
+		}
+		fmt.Fprintf(&sb, "**%s** (%s):\n%s\n\n", author, g.CreatedAt.Format(time.RFC3339), strings.TrimSpace(g.Content))
+	}
+	return sb.String()
+}
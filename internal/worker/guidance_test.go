@@ -0,0 +1,102 @@
+package worker
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// recordingMockProvider answers like sequenceMockProvider and keeps the
+// messages of each request
+type recordingMockProvider struct {
+	sequenceMockProvider
+	requests [][]provider.ChatMessage
+}
+
+func (m *recordingMockProvider) CreateChatCompletion(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	m.requests = append(m.requests, req.Messages)
+	return m.sequenceMockProvider.CreateChatCompletion(ctx, req)
+}
+
+// fakeGuidance has a comment posted once the loop has checked twice
+type fakeGuidance struct {
+	checks int
+	acked  []int
+}
+
+func (f *fakeGuidance) PendingGuidance(beadID string, since time.Time) ([]Guidance, error) {
+	f.checks++
+	if f.checks < 2 {
+		return nil, nil
+	}
+	return []Guidance{{CommentID: "c1", Author: "alice", Content: "Use the v2 API instead.", CreatedAt: time.Now()}}, nil
+}
+
+func (f *fakeGuidance) AcknowledgeGuidance(projectID, beadID, agentID string, iteration int, guidance []Guidance) {
+	f.acked = append(f.acked, iteration)
+}
+
+func TestWorker_ExecuteTaskWithLoop_InjectsGuidanceOnce(t *testing.T) {
+	mock := &recordingMockProvider{sequenceMockProvider: sequenceMockProvider{
+		responses: []string{
+			`{"actions": [{"type": "write_file", "path": "a.go"}]}`,
+			`{"actions": [{"type": "write_file", "path": "a.go"}]}`,
+			`{"actions": [{"type": "write_file", "path": "a.go"}]}`,
+			`{"action": "done", "reason": "switched to v2"}`,
+		},
+	}}
+	rp := &provider.RegisteredProvider{
+		Config:   &provider.ProviderConfig{ID: "p1", Name: "P", Model: "m"},
+		Protocol: mock,
+	}
+	w := NewWorker("w1", &models.Agent{ID: "a1", Name: "Agent"}, rp)
+	_ = w.Start()
+
+	gp := &fakeGuidance{}
+	task := &Task{ID: "t1", BeadID: "b1", ProjectID: "p1", Description: "do something"}
+	config := &LoopConfig{
+		MaxIterations: 5,
+		Router:        &actions.Router{},
+		ActionContext: actions.ActionContext{ProjectID: "p1", BeadID: "b1"},
+		Guidance:      gp,
+		TextMode:      true,
+	}
+
+	result, err := w.ExecuteTaskWithLoop(context.Background(), task, config)
+	if err != nil {
+		t.Fatalf("ExecuteTaskWithLoop error = %v", err)
+	}
+	if result.TerminalReason != "completed" {
+		t.Fatalf("TerminalReason = %q, want completed", result.TerminalReason)
+	}
+	if len(gp.acked) != 1 || gp.acked[0] != 3 {
+		t.Errorf("expected guidance acknowledged once in iteration 3, got %v", gp.acked)
+	}
+
+	// The third call carries the comment as its latest message; the first two do not
+	for i, msgs := range mock.requests {
+		last := msgs[len(msgs)-1].Content
+		injected := strings.Contains(last, "Guidance From a Human") && strings.Contains(last, "Use the v2 API instead.")
+		if injected != (i == 2) {
+			t.Errorf("call %d: guidance injected = %v", i+1, injected)
+		}
+	}
+
+	var logged int
+	for _, entry := range result.ActionLog {
+		if len(entry.Guidance) > 0 {
+			logged++
+			if entry.Iteration != 3 || entry.Guidance[0].Author != "alice" {
+				t.Errorf("unexpected guidance entry %+v", entry)
+			}
+		}
+	}
+	if logged != 1 {
+		t.Errorf("expected one guidance entry in the action log, got %d", logged)
+	}
+}
@@ -0,0 +1,389 @@
+package worker
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// historyBudgetFraction is the share of the model's context window that
+	// prior dispatch history may take; the rest is left for the new dispatch
+	historyBudgetFraction = 0.4
+
+	// recentHistoryShare is the share of the history budget kept verbatim
+	recentHistoryShare = 0.6
+
+	// legacySegmentSize splits sessions recorded before dispatch boundaries
+	// were tracked into pseudo-dispatches of this many messages
+	legacySegmentSize = 20
+
+	// dispatchBoundariesKey is the conversation metadata key holding the
+	// message index at which each dispatch started
+	dispatchBoundariesKey = "dispatch_boundaries"
+
+	maxDigestDecisions = 5
+	maxDigestErrors    = 3
+	maxDigestLineLen   = 200
+)
+
+// markDispatchStart records that a new dispatch begins at the current end
+// of the conversation
+func markDispatchStart(c *models.ConversationContext) {
+	if c.Metadata == nil {
+		c.Metadata = make(map[string]string)
+	}
+	idx := strconv.Itoa(len(c.Messages))
+	existing := c.Metadata[dispatchBoundariesKey]
+	if existing == "" {
+		c.Metadata[dispatchBoundariesKey] = idx
+		return
+	}
+	if parts := strings.Split(existing, ","); parts[len(parts)-1] == idx {
+		return
+	}
+	c.Metadata[dispatchBoundariesKey] = existing + "," + idx
+}
+
+// dispatchBoundaries returns the message indices at which dispatches started
+func dispatchBoundaries(c *models.ConversationContext) []int {
+	var out []int
+	for _, s := range strings.Split(c.Metadata[dispatchBoundariesKey], ",") {
+		if n, err := strconv.Atoi(s); err == nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// historySegment is the run of messages produced by one dispatch
+type historySegment struct {
+	dispatch int // 1-based
+	messages []models.ChatMessage
+}
+
+// compressHistory fits a bead's conversation history into budgetTokens.
+// The system prompt and the most recent dispatches stay verbatim; older
+// dispatches are replaced by digests of their actions, decisions and
+// errors, and when even those do not fit, the oldest digests are merged
+// into one condensed summary.
+func compressHistory(history []models.ChatMessage, boundaries []int, budgetTokens int) []provider.ChatMessage {
+	if len(history) == 0 {
+		return nil
+	}
+	if segmentTokens(history) <= budgetTokens || len(history) < 3 {
+		return toProviderMessages(history)
+	}
+
+	system := history[0]
+	segments := splitHistory(history, boundaries)
+	remaining := budgetTokens - estimateTokens(system.Content)
+	recentBudget := int(float64(remaining) * recentHistoryShare)
+
+	// Keep whole recent dispatches verbatim, newest first, while they fit
+	var verbatim []models.ChatMessage
+	used := 0
+	cut := len(segments)
+	for cut > 0 {
+		size := segmentTokens(segments[cut-1].messages)
+		if used+size > recentBudget {
+			break
+		}
+		verbatim = append(append([]models.ChatMessage{}, segments[cut-1].messages...), verbatim...)
+		used += size
+		cut--
+	}
+	older := segments[:cut]
+
+	// Not even the newest dispatch fits: keep its most recent messages and
+	// digest its beginning with the older dispatches
+	if cut == len(segments) {
+		seg := segments[cut-1].messages
+		start := len(seg)
+		for start > 0 && used+estimateTokens(seg[start-1].Content) <= recentBudget {
+			used += estimateTokens(seg[start-1].Content)
+			start--
+		}
+		verbatim = seg[start:]
+		older = append(segments[:cut-1:cut-1], historySegment{dispatch: segments[cut-1].dispatch, messages: seg[:start]})
+	}
+
+	return assembleHistory(system, digestSegments(older), verbatim, remaining-used)
+}
+
+// assembleHistory lays out the system prompt, digests and verbatim messages,
+// condensing the oldest digests until they fit digestBudget
+func assembleHistory(system models.ChatMessage, digests []*segmentDigest, verbatim []models.ChatMessage, digestBudget int) []provider.ChatMessage {
+	rendered := make([]string, len(digests))
+	size := 0
+	for i, d := range digests {
+		rendered[i] = d.render()
+		size += estimateTokens(rendered[i])
+	}
+
+	// Merge the oldest digests into a condensed one until everything fits
+	merged := 0
+	var condensed *segmentDigest
+	for size > digestBudget && merged < len(digests) {
+		if condensed == nil {
+			condensed = &segmentDigest{first: digests[0].first, condensed: true}
+		} else {
+			size -= estimateTokens(condensed.render())
+		}
+		condensed.merge(digests[merged])
+		size -= estimateTokens(rendered[merged])
+		size += estimateTokens(condensed.render())
+		merged++
+	}
+
+	out := []provider.ChatMessage{{Role: system.Role, Content: system.Content}}
+	if condensed != nil {
+		content := condensed.render()
+		if maxLen := digestBudget * 4; maxLen > 0 && len(content) > maxLen {
+			content = content[:maxLen] + "\n... (older history omitted)"
+		}
+		out = append(out, provider.ChatMessage{Role: "system", Content: content})
+	}
+	for i := merged; i < len(digests); i++ {
+		out = append(out, provider.ChatMessage{Role: "system", Content: rendered[i]})
+	}
+	return append(out, toProviderMessages(verbatim)...)
+}
+
+// splitHistory divides the messages after the system prompt into
+// per-dispatch segments
+func splitHistory(history []models.ChatMessage, boundaries []int) []historySegment {
+	body := history[1:]
+	var starts []int
+	for _, b := range boundaries {
+		if b-1 > 0 && b-1 < len(body) && (len(starts) == 0 || b-1 > starts[len(starts)-1]) {
+			starts = append(starts, b-1)
+		}
+	}
+	if len(boundaries) == 0 {
+		for i := legacySegmentSize; i < len(body); i += legacySegmentSize {
+			starts = append(starts, i)
+		}
+	}
+
+	var segments []historySegment
+	prev := 0
+	for _, s := range append(starts, len(body)) {
+		if s > prev {
+			segments = append(segments, historySegment{dispatch: len(segments) + 1, messages: body[prev:s]})
+		}
+		prev = s
+	}
+	return segments
+}
+
+// segmentDigest is the summary of one or more dispatches
+type segmentDigest struct {
+	first, last int
+	condensed   bool
+	messages    int
+	actions     map[string]int
+	files       []string
+	decisions   []string
+	errors      []string
+	outcome     string
+}
+
+func digestSegments(segments []historySegment) []*segmentDigest {
+	out := make([]*segmentDigest, 0, len(segments))
+	for _, s := range segments {
+		if len(s.messages) > 0 {
+			out = append(out, digestSegment(s))
+		}
+	}
+	return out
+}
+
+// digestSegment extracts what a dispatch did from its messages: the actions
+// and files from the agent's responses, its notes as decisions, and errors
+// reported back to it
+func digestSegment(s historySegment) *segmentDigest {
+	d := &segmentDigest{first: s.dispatch, last: s.dispatch, messages: len(s.messages), actions: make(map[string]int)}
+	for _, m := range s.messages {
+		switch m.Role {
+		case "assistant":
+			env, err := actions.DecodeLenient([]byte(m.Content))
+			if err != nil {
+				env, err = actions.ParseSimpleJSON([]byte(m.Content))
+			}
+			if err != nil || env == nil {
+				continue
+			}
+			for _, a := range withEdits(env.Actions) {
+				d.actions[a.Type]++
+				if a.Path != "" && isFileChange(a.Type) && !containsString(d.files, a.Path) {
+					d.files = append(d.files, a.Path)
+				}
+				d.outcome = a.Type
+			}
+			if note := firstLine(env.Notes); note != "" {
+				d.decisions = appendCapped(d.decisions, note, maxDigestDecisions)
+			}
+		case "user":
+			for _, line := range strings.Split(m.Content, "\n") {
+				// The progress line counts errors without reporting one
+				if strings.HasPrefix(line, "Iteration ") {
+					continue
+				}
+				lower := strings.ToLower(line)
+				if strings.Contains(lower, "error") || strings.Contains(lower, "failed") {
+					if line = firstLine(line); line != "" && !containsString(d.errors, line) {
+						d.errors = appendCapped(d.errors, line, maxDigestErrors)
+					}
+					break
+				}
+			}
+		}
+	}
+	return d
+}
+
+// merge folds another digest into a condensed one, keeping decisions and
+// changed files but not per-dispatch errors
+func (d *segmentDigest) merge(o *segmentDigest) {
+	if d.actions == nil {
+		d.actions = make(map[string]int)
+	}
+	d.last = o.last
+	d.messages += o.messages
+	for k, v := range o.actions {
+		d.actions[k] += v
+	}
+	for _, f := range o.files {
+		if !containsString(d.files, f) {
+			d.files = append(d.files, f)
+		}
+	}
+	for _, dec := range o.decisions {
+		d.decisions = appendCapped(d.decisions, dec, maxDigestDecisions)
+	}
+	d.outcome = o.outcome
+}
+
+func (d *segmentDigest) render() string {
+	var sb strings.Builder
+	switch {
+	case d.condensed && d.first != d.last:
+		sb.WriteString(fmt.Sprintf("[Condensed summary of dispatches %d-%d (%d messages)]\n", d.first, d.last, d.messages))
+	case d.condensed:
+		sb.WriteString(fmt.Sprintf("[Condensed summary of dispatch %d (%d messages)]\n", d.first, d.messages))
+	default:
+		sb.WriteString(fmt.Sprintf("[Summary of dispatch %d (%d messages)]\n", d.first, d.messages))
+	}
+
+	if len(d.actions) > 0 {
+		types := make([]string, 0, len(d.actions))
+		for t := range d.actions {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		parts := make([]string, len(types))
+		for i, t := range types {
+			parts[i] = fmt.Sprintf("%s x%d", t, d.actions[t])
+		}
+		sb.WriteString("Actions: " + strings.Join(parts, ", ") + "\n")
+	}
+	if len(d.files) > 0 {
+		sb.WriteString("Files changed: " + strings.Join(d.files, ", ") + "\n")
+	}
+	if len(d.decisions) > 0 {
+		sb.WriteString("Key decisions:\n")
+		for _, dec := range d.decisions {
+			sb.WriteString("- " + dec + "\n")
+		}
+	}
+	if len(d.errors) > 0 && !d.condensed {
+		sb.WriteString("Errors:\n")
+		for _, e := range d.errors {
+			sb.WriteString("- " + e + "\n")
+		}
+	}
+	if d.outcome != "" {
+		sb.WriteString("Last action: " + d.outcome + "\n")
+	}
+	return sb.String()
+}
+
+// isHistoryDigest reports whether a message is a digest produced by
+// compressHistory rather than part of the recorded conversation
+func isHistoryDigest(content string) bool {
+	return strings.HasPrefix(content, "[Summary of dispatch ") || strings.HasPrefix(content, "[Condensed summary of dispatch")
+}
+
+func isFileChange(actionType string) bool {
+	switch actionType {
+	case actions.ActionWriteFile, actions.ActionEditCode, actions.ActionApplyPatch,
+		actions.ActionExtractMethod, actions.ActionRenameSymbol, actions.ActionInlineVariable:
+		return true
+	}
+	return false
+}
+
+// withEdits lists actions with the edits of each edit_transaction after it
+func withEdits(acts []actions.Action) []actions.Action {
+	out := make([]actions.Action, 0, len(acts))
+	for _, a := range acts {
+		out = append(out, a)
+		out = append(out, a.Edits...)
+	}
+	return out
+}
+
+// appendCapped appends s, dropping the oldest entry once the list is full
+func appendCapped(list []string, s string, max int) []string {
+	list = append(list, s)
+	if len(list) > max {
+		list = list[len(list)-max:]
+	}
+	return list
+}
+
+func firstLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	if len(s) > maxDigestLineLen {
+		s = s[:maxDigestLineLen] + "..."
+	}
+	return s
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentTokens(msgs []models.ChatMessage) int {
+	n := 0
+	for _, m := range msgs {
+		n += estimateTokens(m.Content)
+	}
+	return n
+}
+
+func estimateTokens(s string) int {
+	return len(s) / 4
+}
+
+func toProviderMessages(history []models.ChatMessage) []provider.ChatMessage {
+	out := make([]provider.ChatMessage, len(history))
+	for i, m := range history {
+		out[i] = provider.ChatMessage{Role: m.Role, Content: m.Content}
+	}
+	return out
+}
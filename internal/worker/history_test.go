@@ -0,0 +1,128 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// buildDispatchHistory simulates n dispatches of a bead, each with a few
+// iterations, recording boundaries the way the action loop does
+func buildDispatchHistory(n int) *models.ConversationContext {
+	c := models.NewConversationContext("s1", "bead-1", "proj-1", 0)
+	c.AddMessage("system", "You are an agent.", 4)
+	for d := 1; d <= n; d++ {
+		markDispatchStart(c)
+		for i := 0; i < 3; i++ {
+			resp := fmt.Sprintf(`{"action":"edit","path":"pkg/file%d.go","old":"a","new":"b","notes":"Dispatch %d: decided to patch file%d because %s"}`,
+				d, d, d, strings.Repeat("the parser drops tokens ", 20))
+			c.AddMessage("assistant", resp, len(resp)/4)
+			c.AddMessage("user", fmt.Sprintf("Result of iteration %d:\nerror: build failed in file%d.go\n%s", i, d, strings.Repeat("output line\n", 40)), 0)
+		}
+	}
+	return c
+}
+
+func TestCompressHistory_FitsUnchanged(t *testing.T) {
+	c := buildDispatchHistory(2)
+	got := compressHistory(c.Messages, dispatchBoundaries(c), 1_000_000)
+	if len(got) != len(c.Messages) {
+		t.Fatalf("expected %d verbatim messages, got %d", len(c.Messages), len(got))
+	}
+	for _, m := range got {
+		if isHistoryDigest(m.Content) {
+			t.Error("no digests expected when history fits")
+		}
+	}
+}
+
+func TestCompressHistory_SummarizesOlderDispatches(t *testing.T) {
+	c := buildDispatchHistory(8)
+	full := segmentTokens(c.Messages)
+	budget := full / 3
+
+	got := compressHistory(c.Messages, dispatchBoundaries(c), budget)
+
+	size := 0
+	for _, m := range got {
+		size += estimateTokens(m.Content)
+	}
+	if size > budget {
+		t.Errorf("compressed history uses %d tokens, budget %d", size, budget)
+	}
+	if got[0].Content != "You are an agent." {
+		t.Errorf("system prompt must stay first, got %q", got[0].Content)
+	}
+
+	// The newest dispatch is kept verbatim
+	last := got[len(got)-1].Content
+	if last != c.Messages[len(c.Messages)-1].Content {
+		t.Error("most recent message should be verbatim")
+	}
+
+	// Older dispatches survive as digests that keep their decisions
+	var digests []string
+	for _, m := range got {
+		if isHistoryDigest(m.Content) {
+			digests = append(digests, m.Content)
+		}
+	}
+	if len(digests) == 0 {
+		t.Fatal("expected digests of older dispatches")
+	}
+	joined := strings.Join(digests, "\n")
+	for _, want := range []string{"edit_code x3", "Files changed: pkg/file1.go", "Dispatch 1: decided to patch file1", "error: build failed in file1.go"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("digests missing %q:\n%s", want, joined)
+		}
+	}
+}
+
+func TestCompressHistory_CondensesWhenDigestsOverflow(t *testing.T) {
+	c := buildDispatchHistory(40)
+	budget := 1500
+
+	got := compressHistory(c.Messages, dispatchBoundaries(c), budget)
+	size := 0
+	condensed := 0
+	for _, m := range got {
+		size += estimateTokens(m.Content)
+		if strings.HasPrefix(m.Content, "[Condensed summary of dispatches 1-") {
+			condensed++
+		}
+	}
+	if condensed != 1 {
+		t.Fatalf("expected one condensed summary of the oldest dispatches, got %d", condensed)
+	}
+	// Allow for the omission marker when the condensed summary is clipped
+	if size > budget+10 {
+		t.Errorf("compressed history uses %d tokens, budget %d", size, budget)
+	}
+}
+
+func TestSplitHistory_LegacySessions(t *testing.T) {
+	c := buildDispatchHistory(5) // 30 messages after the system prompt
+	segments := splitHistory(c.Messages, nil)
+	if len(segments) != 2 || len(segments[0].messages) != legacySegmentSize {
+		t.Errorf("expected legacy sessions split every %d messages, got %d segments", legacySegmentSize, len(segments))
+	}
+
+	segments = splitHistory(c.Messages, dispatchBoundaries(c))
+	if len(segments) != 5 || segments[4].dispatch != 5 {
+		t.Errorf("expected 5 dispatch segments, got %d", len(segments))
+	}
+}
+
+func TestMarkDispatchStart_Idempotent(t *testing.T) {
+	c := models.NewConversationContext("s1", "bead-1", "proj-1", 0)
+	c.AddMessage("system", "sys", 1)
+	markDispatchStart(c)
+	markDispatchStart(c)
+	c.AddMessage("assistant", "{}", 1)
+	markDispatchStart(c)
+	if got := c.Metadata[dispatchBoundariesKey]; got != "1,2" {
+		t.Errorf("boundaries = %q, want %q", got, "1,2")
+	}
+}
@@ -0,0 +1,75 @@
+package worker
+
+import (
+	"log"
+	"strings"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// LessonOutcomeRecorder is implemented by lessons providers that score
+// lessons by how the dispatches they went into ended. SelectLessons is
+// GetRelevantLessons returning the IDs of the lessons it chose.
+type LessonOutcomeRecorder interface {
+	SelectLessons(projectID, taskContext string, topK int) (string, []string)
+	RecordLessonOutcome(projectID, beadID, dispatchID string, lessonIDs []string, outcome string, succeeded bool) error
+}
+
+// lessonIDsKey is the conversation metadata holding the lessons in the
+// system prompt, which later dispatches of the bead reuse
+const lessonIDsKey = "lesson_ids"
+
+// storeLessonIDs records the lessons in a new system prompt with the
+// conversation
+func storeLessonIDs(c *models.ConversationContext, ids []string) {
+	if c == nil || c.Metadata == nil {
+		return
+	}
+	if len(ids) == 0 {
+		delete(c.Metadata, lessonIDsKey)
+		return
+	}
+	c.Metadata[lessonIDsKey] = strings.Join(ids, ",")
+}
+
+// storedLessonIDs returns the lessons in a conversation's system prompt
+func storedLessonIDs(c *models.ConversationContext) []string {
+	if c == nil || c.Metadata[lessonIDsKey] == "" {
+		return nil
+	}
+	return strings.Split(c.Metadata[lessonIDsKey], ",")
+}
+
+// lessonOutcome reports whether a loop that ended for reason succeeded,
+// and whether its outcome says anything about the lessons it was given.
+// A handed off task, or a plan, is neither a success nor a failure of this
+// dispatch.
+func lessonOutcome(reason string) (succeeded, counted bool) {
+	switch reason {
+	case "completed":
+		return true, true
+	case "handed_off", "planned", "context_canceled", "":
+		return false, false
+	default:
+		return false, true
+	}
+}
+
+// recordLessonOutcome credits the lessons in a dispatch's prompt with how
+// the dispatch ended
+func (w *Worker) recordLessonOutcome(config *LoopConfig, task *Task, lessonIDs []string, reason string) {
+	if len(lessonIDs) == 0 || task.ProjectID == "" {
+		return
+	}
+	rec, ok := config.LessonsProvider.(LessonOutcomeRecorder)
+	if !ok {
+		return
+	}
+	succeeded, counted := lessonOutcome(reason)
+	if !counted {
+		return
+	}
+	if err := rec.RecordLessonOutcome(task.ProjectID, task.BeadID, task.ID, lessonIDs, reason, succeeded); err != nil {
+		log.Printf("[ActionLoop] Warning: Failed to record lesson outcome: %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// outcomeLessonsProvider selects fixed lessons and records outcomes
+type outcomeLessonsProvider struct {
+	mockLessonsProvider
+	ids      []string
+	recorded []string
+	outcomes []bool
+}
+
+func (p *outcomeLessonsProvider) SelectLessons(projectID, taskContext string, topK int) (string, []string) {
+	return "### TEST: Run the tests\n- before pushing\n", p.ids
+}
+
+func (p *outcomeLessonsProvider) RecordLessonOutcome(projectID, beadID, dispatchID string, lessonIDs []string, outcome string, succeeded bool) error {
+	p.recorded = append(p.recorded, lessonIDs...)
+	p.outcomes = append(p.outcomes, succeeded)
+	return nil
+}
+
+func TestBuildDispatchMessages_ReportsLessonIDs(t *testing.T) {
+	w := makeTestWorker(nil)
+	c := models.NewConversationContext("s1", "bead-1", "proj-1", 0)
+	task := &Task{ID: "t1", Description: "Do the thing", ProjectID: "proj-1"}
+	config := &LoopConfig{LessonsProvider: &outcomeLessonsProvider{ids: []string{"l1", "l2"}}}
+
+	_, ids := w.buildDispatchMessages(config, task, c)
+	if !reflect.DeepEqual(ids, []string{"l1", "l2"}) {
+		t.Fatalf("expected the selected lessons, got %v", ids)
+	}
+
+	// A later dispatch reuses the stored prompt and so its lessons
+	markDispatchStart(c)
+	c.AddMessage("assistant", `{"action":"done"}`, 4)
+	_, ids = w.buildDispatchMessages(&LoopConfig{}, task, c)
+	if !reflect.DeepEqual(ids, []string{"l1", "l2"}) {
+		t.Errorf("expected the stored prompt's lessons, got %v", ids)
+	}
+}
+
+func TestRecordLessonOutcome(t *testing.T) {
+	w := makeTestWorker(nil)
+	lp := &outcomeLessonsProvider{}
+	config := &LoopConfig{LessonsProvider: lp}
+	task := &Task{ID: "t1", ProjectID: "proj-1", BeadID: "bead-1"}
+
+	w.recordLessonOutcome(config, task, []string{"l1"}, "completed")
+	w.recordLessonOutcome(config, task, []string{"l2"}, "max_iterations")
+	w.recordLessonOutcome(config, task, []string{"l3"}, "handed_off")
+	w.recordLessonOutcome(config, task, nil, "completed")
+
+	if !reflect.DeepEqual(lp.recorded, []string{"l1", "l2"}) || !reflect.DeepEqual(lp.outcomes, []bool{true, false}) {
+		t.Errorf("expected l1 succeeded and l2 failed, got %v %v", lp.recorded, lp.outcomes)
+	}
+
+	// Providers that do not score lessons are left alone
+	w.recordLessonOutcome(&LoopConfig{LessonsProvider: &mockLessonsProvider{}}, task, []string{"l1"}, "completed")
+}
@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// DefaultDowngradeAfter is how many simple iterations in a row move an
+// action loop to a cheaper model
+const DefaultDowngradeAfter = 3
+
+// ModelSwitcher finds providers of neighbouring model tiers, so an action
+// loop can move to a stronger model when it struggles and to a cheaper one
+// while its work is simple
+type ModelSwitcher interface {
+	// NextTier returns the provider of the nearest model tier above (or
+	// below) current's that may serve the project, or nil if there is none
+	NextTier(projectID string, current *provider.RegisteredProvider, up bool) *provider.RegisteredProvider
+	// RecordModelSwitch records that an agent's loop on a bead moved to
+	// another model before an iteration
+	RecordModelSwitch(projectID, beadID, agentID string, iteration int, sw *ModelSwitch)
+}
+
+// ModelSwitch records an action loop moving to another model mid-bead.
+// The conversation carries over to the new model.
+type ModelSwitch struct {
+	Direction    string `json:"direction"` // "upgrade" or "downgrade"
+	Reason       string `json:"reason"`
+	FromProvider string `json:"from_provider"`
+	FromModel    string `json:"from_model"`
+	FromTier     string `json:"from_tier"`
+	ToProvider   string `json:"to_provider"`
+	ToModel      string `json:"to_model"`
+	ToTier       string `json:"to_tier"`
+}
+
+// modelTiering decides when an action loop changes model tier: up when
+// the model keeps failing, down after a run of simple iterations, and back
+// up when the work stops being simple
+type modelTiering struct {
+	switcher       ModelSwitcher
+	projectID      string
+	beadID         string
+	downgradeAfter int
+	simpleStreak   int
+	// failed holds the providers the loop moved up from, which it does
+	// not move back down to
+	failed map[string]bool
+	// beforeDowngrade is the provider to return to once the work is no
+	// longer simple
+	beforeDowngrade *provider.RegisteredProvider
+}
+
+// newModelTiering returns the tiering of a loop, or nil when it keeps its
+// model throughout
+func newModelTiering(config *LoopConfig, task *Task) *modelTiering {
+	if config.ModelSwitcher == nil {
+		return nil
+	}
+	after := config.DowngradeAfter
+	if after == 0 {
+		after = DefaultDowngradeAfter
+	}
+	return &modelTiering{
+		switcher:       config.ModelSwitcher,
+		projectID:      task.ProjectID,
+		beadID:         task.BeadID,
+		downgradeAfter: after,
+		failed:         make(map[string]bool),
+	}
+}
+
+// upgrade moves the loop to the next tier up after a failure before
+// iteration, reporting whether it did
+func (t *modelTiering) upgrade(w *Worker, loopResult *LoopResult, iteration int, reason string) bool {
+	if t == nil {
+		return false
+	}
+	current := w.currentProvider()
+	next := t.switcher.NextTier(t.projectID, current, true)
+	if next == nil {
+		return false
+	}
+	t.failed[current.Config.ID] = true
+	t.simpleStreak = 0
+	t.beforeDowngrade = nil
+	t.record(w, iteration, w.switchModel(loopResult, iteration, next, "upgrade", reason))
+	return true
+}
+
+// observe notes the actions of an iteration, moving the loop down a tier
+// after downgradeAfter simple iterations in a row, and back to the model
+// it moved down from once an iteration is not simple
+func (t *modelTiering) observe(w *Worker, loopResult *LoopResult, iteration int, env *actions.ActionEnvelope, results []actions.Result) {
+	if t == nil {
+		return
+	}
+	if !simpleIteration(env, results) {
+		t.simpleStreak = 0
+		if t.beforeDowngrade != nil {
+			back := t.beforeDowngrade
+			t.beforeDowngrade = nil
+			t.record(w, iteration, w.switchModel(loopResult, iteration, back, "upgrade", "the work is no longer simple"))
+		}
+		return
+	}
+
+	t.simpleStreak++
+	if t.downgradeAfter < 0 || t.simpleStreak < t.downgradeAfter {
+		return
+	}
+	t.simpleStreak = 0
+	current := w.currentProvider()
+	next := t.switcher.NextTier(t.projectID, current, false)
+	if next == nil || t.failed[next.Config.ID] {
+		return
+	}
+	if t.beforeDowngrade == nil {
+		t.beforeDowngrade = current
+	}
+	t.record(w, iteration, w.switchModel(loopResult, iteration, next, "downgrade", fmt.Sprintf("%d simple iterations in a row", t.downgradeAfter)))
+}
+
+func (t *modelTiering) record(w *Worker, iteration int, sw *ModelSwitch) {
+	t.switcher.RecordModelSwitch(t.projectID, t.beadID, w.agent.ID, iteration, sw)
+}
+
+// simpleIteration reports whether an iteration only read the project, and
+// did so without errors
+func simpleIteration(env *actions.ActionEnvelope, results []actions.Result) bool {
+	for _, a := range env.Actions {
+		if !planReadActions[a.Type] {
+			return false
+		}
+	}
+	for _, r := range results {
+		if r.Status == "error" {
+			return false
+		}
+	}
+	return len(env.Actions) > 0
+}
+
+// currentProvider returns the provider the worker is using
+func (w *Worker) currentProvider() *provider.RegisteredProvider {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.provider
+}
+
+// useProvider points the worker at another provider and returns the one it
+// replaces. Whether the provider refused response schemas is not carried
+// over.
+func (w *Worker) useProvider(rp *provider.RegisteredProvider) *provider.RegisteredProvider {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	prev := w.provider
+	if rp != prev {
+		w.provider = rp
+		w.schemaUnsupported = false
+	}
+	return prev
+}
+
+// switchModel moves the loop to another provider from iteration on and
+// records the switch in that iteration's action log
+func (w *Worker) switchModel(loopResult *LoopResult, iteration int, to *provider.RegisteredProvider, direction, reason string) *ModelSwitch {
+	from := w.useProvider(to)
+	sw := &ModelSwitch{
+		Direction:    direction,
+		Reason:       reason,
+		FromProvider: from.Config.ID,
+		FromModel:    from.Config.Model,
+		FromTier:     provider.GetModelTier(from.Config.ModelParamsB).String(),
+		ToProvider:   to.Config.ID,
+		ToModel:      to.Config.Model,
+		ToTier:       provider.GetModelTier(to.Config.ModelParamsB).String(),
+	}
+	loopResult.ActionLog = append(loopResult.ActionLog, ActionLogEntry{
+		Iteration:   iteration,
+		ModelSwitch: sw,
+		Timestamp:   time.Now(),
+	})
+	log.Printf("[ActionLoop] %s from %s (%s) to %s (%s) before iteration %d: %s",
+		direction, sw.FromModel, sw.FromTier, sw.ToModel, sw.ToTier, iteration, reason)
+	return sw
+}
@@ -0,0 +1,119 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// tierSwitcher moves between a fixed ladder of providers, smallest first
+type tierSwitcher struct {
+	ladder   []*provider.RegisteredProvider
+	recorded []*ModelSwitch
+}
+
+func (s *tierSwitcher) NextTier(projectID string, current *provider.RegisteredProvider, up bool) *provider.RegisteredProvider {
+	for i, rp := range s.ladder {
+		if rp != current {
+			continue
+		}
+		if up && i+1 < len(s.ladder) {
+			return s.ladder[i+1]
+		}
+		if !up && i > 0 {
+			return s.ladder[i-1]
+		}
+	}
+	return nil
+}
+
+func (s *tierSwitcher) RecordModelSwitch(projectID, beadID, agentID string, iteration int, sw *ModelSwitch) {
+	s.recorded = append(s.recorded, sw)
+}
+
+func tierProvider(id string, paramsB float64, responses ...string) *provider.RegisteredProvider {
+	return &provider.RegisteredProvider{
+		Config:   &provider.ProviderConfig{ID: id, Model: id + "-model", ModelParamsB: paramsB},
+		Protocol: &sequenceMockProvider{responses: responses},
+	}
+}
+
+func TestWorker_ExecuteTaskWithLoop_UpgradesAfterParseFailures(t *testing.T) {
+	small := tierProvider("small", 7, "not json at all", "still not json")
+	large := tierProvider("large", 70, `{"action": "done", "reason": "fixed"}`)
+	switcher := &tierSwitcher{ladder: []*provider.RegisteredProvider{small, large}}
+
+	w := NewWorker("w1", &models.Agent{ID: "a1", Name: "Agent"}, small)
+	_ = w.Start()
+	result, err := w.ExecuteTaskWithLoop(context.Background(), &Task{ID: "t1", BeadID: "b1", ProjectID: "p1", Description: "fix it"}, &LoopConfig{
+		MaxIterations: 5,
+		Router:        &actions.Router{},
+		ActionContext: actions.ActionContext{ProjectID: "p1", BeadID: "b1"},
+		ModelSwitcher: switcher,
+		TextMode:      true,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteTaskWithLoop error = %v", err)
+	}
+	if result.TerminalReason != "completed" {
+		t.Fatalf("TerminalReason = %q, want completed on the larger model", result.TerminalReason)
+	}
+	if len(switcher.recorded) != 1 || switcher.recorded[0].Direction != "upgrade" || switcher.recorded[0].ToTier != "large" {
+		t.Fatalf("expected one recorded upgrade to the large tier, got %+v", switcher.recorded)
+	}
+	var logged *ActionLogEntry
+	for i := range result.ActionLog {
+		if result.ActionLog[i].ModelSwitch != nil {
+			logged = &result.ActionLog[i]
+		}
+	}
+	if logged == nil || logged.Iteration != 3 || logged.ModelSwitch.FromModel != "small-model" {
+		t.Errorf("expected the upgrade in the action log before iteration 3, got %+v", logged)
+	}
+	if w.currentProvider() != small {
+		t.Error("expected the worker back on its own provider after the loop")
+	}
+}
+
+func TestModelTiering_DowngradesSimpleWorkAndReturns(t *testing.T) {
+	small := tierProvider("small", 7)
+	medium := tierProvider("medium", 30)
+	switcher := &tierSwitcher{ladder: []*provider.RegisteredProvider{small, medium}}
+	w := NewWorker("w1", &models.Agent{ID: "a1"}, medium)
+	tiering := newModelTiering(&LoopConfig{ModelSwitcher: switcher, DowngradeAfter: 2}, &Task{ProjectID: "p1", BeadID: "b1"})
+	loopResult := &LoopResult{TaskResult: &TaskResult{}}
+
+	read := &actions.ActionEnvelope{Actions: []actions.Action{{Type: actions.ActionReadFile, Path: "a.go"}}}
+	readOK := []actions.Result{{ActionType: actions.ActionReadFile, Status: "executed"}}
+	write := &actions.ActionEnvelope{Actions: []actions.Action{{Type: actions.ActionWriteFile, Path: "a.go"}}}
+
+	tiering.observe(w, loopResult, 2, read, readOK)
+	if w.currentProvider() != medium {
+		t.Fatal("one simple iteration should not move the loop")
+	}
+	tiering.observe(w, loopResult, 3, read, readOK)
+	if w.currentProvider() != small {
+		t.Fatal("expected the loop on the smaller model after two simple iterations")
+	}
+	tiering.observe(w, loopResult, 4, write, []actions.Result{{ActionType: actions.ActionWriteFile, Status: "executed"}})
+	if w.currentProvider() != medium {
+		t.Fatal("expected the loop back on its model once the work is not simple")
+	}
+
+	// A model the loop moved up from is not moved back down to
+	w.useProvider(small)
+	if !tiering.upgrade(w, loopResult, 5, "two consecutive parse failures") || w.currentProvider() != medium {
+		t.Fatal("expected an upgrade to the medium model")
+	}
+	tiering.observe(w, loopResult, 6, read, readOK)
+	tiering.observe(w, loopResult, 7, read, readOK)
+	if w.currentProvider() != medium {
+		t.Error("expected no downgrade to a model that failed in this loop")
+	}
+	if got := len(switcher.recorded); got != 3 {
+		t.Errorf("recorded %d switches, want 3", got)
+	}
+}
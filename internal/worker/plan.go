@@ -0,0 +1,150 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// planReadActions are the actions an agent in plan mode performs; every
+// other action is recorded in its plan instead
+var planReadActions = map[string]bool{
+	actions.ActionReadCode:            true,
+	actions.ActionReadFile:            true,
+	actions.ActionReadTree:            true,
+	actions.ActionSearchText:          true,
+	actions.ActionGitStatus:           true,
+	actions.ActionGitDiff:             true,
+	actions.ActionGitLog:              true,
+	actions.ActionStaticAnalysis:      true,
+	actions.ActionGitListBranches:     true,
+	actions.ActionGitDiffBranches:     true,
+	actions.ActionGitBeadCommits:      true,
+	actions.ActionFindReferences:      true,
+	actions.ActionGoToDefinition:      true,
+	actions.ActionFindImplementations: true,
+}
+
+// planTerminalActions end a plan mode loop: the agent is done planning
+var planTerminalActions = map[string]bool{
+	actions.ActionDone:        true,
+	actions.ActionCloseBead:   true,
+	actions.ActionHandoff:     true,
+	actions.ActionEscalateCEO: true,
+}
+
+// planStatus is the result status of an action recorded in a plan
+const planStatus = "planned"
+
+// executePlanMode runs the read actions of an envelope and records the
+// rest as planned, so the agent learns what it needs without changing
+// anything
+func executePlanMode(run func(*actions.ActionEnvelope) ([]actions.Result, error), env *actions.ActionEnvelope) ([]actions.Result, error) {
+	results := make([]actions.Result, len(env.Actions))
+	reads := &actions.ActionEnvelope{Notes: env.Notes}
+	var readIdx []int
+	for i, a := range env.Actions {
+		if planReadActions[a.Type] {
+			reads.Actions = append(reads.Actions, a)
+			readIdx = append(readIdx, i)
+			continue
+		}
+		results[i] = actions.Result{
+			ActionType: a.Type,
+			Status:     planStatus,
+			Message:    "recorded in your plan, not performed: this is a planning run",
+		}
+	}
+	if len(reads.Actions) > 0 {
+		readResults, err := run(reads)
+		if err != nil {
+			return nil, err
+		}
+		for j, r := range readResults {
+			if j < len(readIdx) {
+				results[readIdx[j]] = r
+			}
+		}
+	}
+	return results, nil
+}
+
+// planTerminated reports whether an envelope ends a plan mode loop
+func planTerminated(env *actions.ActionEnvelope) bool {
+	for _, a := range env.Actions {
+		if planTerminalActions[a.Type] {
+			return true
+		}
+	}
+	return false
+}
+
+// buildPlan gathers the files and commands of the actions an agent in plan
+// mode recorded, in the order it meant to perform them
+func buildPlan(log []ActionLogEntry) *models.ExecutionPlan {
+	plan := &models.ExecutionPlan{}
+	files := make(map[string]bool)
+	commands := make(map[string]bool)
+	addFile := func(p string) {
+		if p != "" && !files[p] {
+			files[p] = true
+			plan.Files = append(plan.Files, p)
+		}
+	}
+	addCommand := func(c string) {
+		if c != "" && !commands[c] {
+			commands[c] = true
+			plan.Commands = append(plan.Commands, c)
+		}
+	}
+
+	for _, entry := range log {
+		for i, a := range entry.Actions {
+			if i >= len(entry.Results) || entry.Results[i].Status != planStatus {
+				continue
+			}
+			if planTerminalActions[a.Type] {
+				if a.Type == actions.ActionDone || a.Type == actions.ActionCloseBead {
+					plan.Summary = a.Reason
+				} else {
+					plan.Steps = append(plan.Steps, planStep(a))
+				}
+				continue
+			}
+			plan.Steps = append(plan.Steps, planStep(a))
+			addFile(a.Path)
+			for _, f := range a.Files {
+				addFile(f)
+			}
+			switch {
+			case a.Command != "":
+				addCommand(a.Command)
+			case a.BuildCommand != "":
+				addCommand(a.BuildCommand)
+			case a.Path == "" && len(a.Files) == 0:
+				addCommand(a.Type)
+			}
+		}
+	}
+	return plan
+}
+
+// planStep describes one recorded action
+func planStep(a actions.Action) string {
+	var detail []string
+	if a.Path != "" {
+		detail = append(detail, a.Path)
+	}
+	if a.Command != "" {
+		detail = append(detail, a.Command)
+	}
+	if a.Reason != "" {
+		detail = append(detail, a.Reason)
+	}
+	if len(detail) == 0 {
+		return a.Type
+	}
+	return fmt.Sprintf("%s: %s", a.Type, strings.Join(detail, " — "))
+}
@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+)
+
+func TestExecutePlanMode_RecordsWritesAndRunsReads(t *testing.T) {
+	env := &actions.ActionEnvelope{Actions: []actions.Action{
+		{Type: actions.ActionReadFile, Path: "main.go"},
+		{Type: actions.ActionWriteFile, Path: "main.go", Content: "package main"},
+		{Type: actions.ActionRunCommand, Command: "go test ./..."},
+		{Type: actions.ActionDone, Reason: "Rewrite main.go and run the tests"},
+	}}
+	var ran []string
+	run := func(e *actions.ActionEnvelope) ([]actions.Result, error) {
+		results := make([]actions.Result, len(e.Actions))
+		for i, a := range e.Actions {
+			ran = append(ran, a.Type)
+			results[i] = actions.Result{ActionType: a.Type, Status: "executed"}
+		}
+		return results, nil
+	}
+
+	results, err := executePlanMode(run, env)
+	if err != nil {
+		t.Fatalf("executePlanMode failed: %v", err)
+	}
+	if len(ran) != 1 || ran[0] != actions.ActionReadFile {
+		t.Errorf("expected only the read to run, ran %v", ran)
+	}
+	want := []string{"executed", planStatus, planStatus, planStatus}
+	for i, r := range results {
+		if r.Status != want[i] {
+			t.Errorf("result %d: status %q, want %q", i, r.Status, want[i])
+		}
+	}
+	if !planTerminated(env) {
+		t.Error("expected done to end the planning loop")
+	}
+
+	plan := buildPlan([]ActionLogEntry{{Iteration: 1, Actions: env.Actions, Results: results}})
+	if plan.Summary != "Rewrite main.go and run the tests" {
+		t.Errorf("Summary = %q", plan.Summary)
+	}
+	if len(plan.Files) != 1 || plan.Files[0] != "main.go" {
+		t.Errorf("Files = %v, want the written file only", plan.Files)
+	}
+	if len(plan.Commands) != 1 || plan.Commands[0] != "go test ./..." {
+		t.Errorf("Commands = %v", plan.Commands)
+	}
+	if len(plan.Steps) != 2 {
+		t.Errorf("Steps = %v, want the write and the command", plan.Steps)
+	}
+}
@@ -17,6 +17,7 @@ type Pool struct {
 	workers    map[string]*Worker
 	registry   *provider.Registry
 	db         *database.Database
+	budget     *ContextBudget
 	mu         sync.RWMutex
 	maxWorkers int
 }
@@ -30,6 +31,13 @@ func NewPool(registry *provider.Registry, maxWorkers int) *Pool {
 	}
 }
 
+// SetMaxWorkers changes how many workers may be spawned
+func (p *Pool) SetMaxWorkers(max int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.maxWorkers = max
+}
+
 // SetDatabase sets the database for conversation context management
 func (p *Pool) SetDatabase(db *database.Database) {
 	p.mu.Lock()
@@ -37,6 +45,16 @@ func (p *Pool) SetDatabase(db *database.Database) {
 	p.db = db
 }
 
+// SetContextBudget sets how prompts are sized for current and future workers
+func (p *Pool) SetContextBudget(b *ContextBudget) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.budget = b
+	for _, w := range p.workers {
+		w.SetContextBudget(b)
+	}
+}
+
 // SpawnWorker creates and starts a new worker for an agent
 func (p *Pool) SpawnWorker(agent *models.Agent, providerID string) (*Worker, error) {
 	p.mu.Lock()
@@ -66,6 +84,7 @@ func (p *Pool) SpawnWorker(agent *models.Agent, providerID string) (*Worker, err
 	if p.db != nil {
 		worker.SetDatabase(p.db)
 	}
+	worker.SetContextBudget(p.budget)
 
 	// Start worker
 	if err := worker.Start(); err != nil {
@@ -48,6 +48,13 @@ func (pt *ProgressTracker) Update(iteration int, results []actions.Result) {
 			if path, _ := r.Metadata["path"].(string); path != "" {
 				pt.filesWritten[path] = true
 			}
+		case actions.ActionEditTransaction:
+			if r.Status == "executed" {
+				paths, _ := r.Metadata["files"].([]string)
+				for _, path := range paths {
+					pt.filesWritten[path] = true
+				}
+			}
 		case actions.ActionBuildProject:
 			if r.Status == "error" || (r.Metadata != nil && r.Metadata["success"] == false) {
 				pt.buildStatus = "fail"
@@ -0,0 +1,86 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/jsonschema"
+	"github.com/jordanhubbard/loom/internal/provider"
+)
+
+// ResponseRepairer rewrites a model response that could not be parsed into
+// JSON matching schema, usually with a cheaper model than the agent's own.
+// problems says what is wrong with the response. Only providers allowed to
+// serve the project the response was written for may repair it.
+type ResponseRepairer interface {
+	RepairResponse(ctx context.Context, projectID, response string, schema *provider.JSONSchema, problems []string) (string, error)
+}
+
+// responseFormat returns the structured output format of action loop
+// requests: the response schema, or plain JSON mode once the provider has
+// refused schemas
+func (w *Worker) responseFormat(textMode bool) *provider.ResponseFormat {
+	w.mu.RLock()
+	unsupported := w.schemaUnsupported
+	w.mu.RUnlock()
+	if unsupported {
+		return &provider.ResponseFormat{Type: "json_object"}
+	}
+	return provider.SchemaFormat(actions.ResponseSchema(textMode))
+}
+
+// createCompletion sends a request, falling back to plain JSON mode for
+// the rest of the worker's life when the provider refuses the schema
+func (w *Worker) createCompletion(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	resp, err := w.provider.CreateChatCompletion(ctx, req)
+	var formatErr *provider.ResponseFormatError
+	if !errors.As(err, &formatErr) {
+		return resp, err
+	}
+	log.Printf("[ActionLoop] Provider %s refused the response schema, using JSON mode: %v", w.provider.Config.ID, formatErr)
+	w.mu.Lock()
+	w.schemaUnsupported = true
+	w.mu.Unlock()
+	req.ResponseFormat = &provider.ResponseFormat{Type: "json_object"}
+	return w.provider.CreateChatCompletion(ctx, req)
+}
+
+// repairResponse has a response that failed to parse rewritten to match
+// the response schema. The repair is only used if it passes the schema
+// and parses. Responses that parsed but left out required fields, or that
+// are chat rather than actions, are not repaired: a repair would have to
+// invent what the model did not say.
+func repairResponse(ctx context.Context, repairer ResponseRepairer, projectID string, textMode bool, response string, parseErr error,
+	parse func([]byte) (*actions.ActionEnvelope, error)) (string, *actions.ActionEnvelope, bool) {
+	var validationErr *actions.ValidationError
+	if errors.As(parseErr, &validationErr) || isConversationalResponse(response) {
+		return "", nil, false
+	}
+	name, raw := actions.ResponseSchema(textMode)
+	schema, err := jsonschema.Parse(raw)
+	if err != nil {
+		return "", nil, false
+	}
+	problems := schema.Validate([]byte(response))
+	if len(problems) == 0 {
+		problems = []string{parseErr.Error()}
+	}
+
+	repaired, err := repairer.RepairResponse(ctx, projectID, response, &provider.JSONSchema{Name: name, Schema: raw}, problems)
+	if err != nil {
+		log.Printf("[ActionLoop] Response repair failed: %v", err)
+		return "", nil, false
+	}
+	if remaining := schema.Validate([]byte(repaired)); len(remaining) > 0 {
+		log.Printf("[ActionLoop] Repaired response still fails the schema: %v", remaining)
+		return "", nil, false
+	}
+	env, err := parse([]byte(repaired))
+	if err != nil {
+		log.Printf("[ActionLoop] Repaired response does not parse: %v", err)
+		return "", nil, false
+	}
+	return repaired, env, true
+}
@@ -0,0 +1,95 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// schemaRefusingProvider rejects json_schema requests the way providers
+// without structured outputs do, and records the formats it was sent
+type schemaRefusingProvider struct {
+	sequenceMockProvider
+	formats []string
+}
+
+func (m *schemaRefusingProvider) CreateChatCompletion(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	m.formats = append(m.formats, req.ResponseFormat.Type)
+	if req.ResponseFormat.Type == "json_schema" {
+		return nil, &provider.ResponseFormatError{StatusCode: 400, Body: "response_format json_schema is not supported"}
+	}
+	return m.sequenceMockProvider.CreateChatCompletion(ctx, req)
+}
+
+// fakeRepairer answers every repair with the same response
+type fakeRepairer struct {
+	repaired string
+	problems []string
+}
+
+func (f *fakeRepairer) RepairResponse(ctx context.Context, projectID, response string, schema *provider.JSONSchema, problems []string) (string, error) {
+	f.problems = problems
+	return f.repaired, nil
+}
+
+func TestWorker_CreateCompletion_FallsBackToJSONMode(t *testing.T) {
+	mock := &schemaRefusingProvider{sequenceMockProvider: sequenceMockProvider{responses: []string{`{"action": "done"}`}}}
+	w := NewWorker("w1", &models.Agent{ID: "a1"}, &provider.RegisteredProvider{
+		Config:   &provider.ProviderConfig{ID: "p1", Model: "m"},
+		Protocol: mock,
+	})
+
+	for i := 0; i < 2; i++ {
+		req := &provider.ChatCompletionRequest{ResponseFormat: w.responseFormat(true)}
+		if _, err := w.createCompletion(context.Background(), req); err != nil {
+			t.Fatalf("createCompletion error = %v", err)
+		}
+	}
+	want := []string{"json_schema", "json_object", "json_object"}
+	if strings.Join(mock.formats, ",") != strings.Join(want, ",") {
+		t.Errorf("formats sent = %v, want %v", mock.formats, want)
+	}
+}
+
+func TestWorker_ExecuteTaskWithLoop_RepairsMalformedResponse(t *testing.T) {
+	mock := &sequenceMockProvider{responses: []string{`{"action": "done", "reason": "finished"`}}
+	w := NewWorker("w1", &models.Agent{ID: "a1", Name: "Agent"}, &provider.RegisteredProvider{
+		Config:   &provider.ProviderConfig{ID: "p1", Model: "m"},
+		Protocol: mock,
+	})
+	_ = w.Start()
+
+	repairer := &fakeRepairer{repaired: `{"action": "done", "reason": "finished"}`}
+	result, err := w.ExecuteTaskWithLoop(context.Background(), &Task{ID: "t1", BeadID: "b1", ProjectID: "p1", Description: "do it"}, &LoopConfig{
+		MaxIterations: 3,
+		Router:        &actions.Router{},
+		ActionContext: actions.ActionContext{ProjectID: "p1", BeadID: "b1"},
+		Repairer:      repairer,
+		TextMode:      true,
+	})
+	if err != nil {
+		t.Fatalf("ExecuteTaskWithLoop error = %v", err)
+	}
+	if result.TerminalReason != "completed" || result.ResponseRepairs != 1 {
+		t.Errorf("TerminalReason = %q, ResponseRepairs = %d; want completed after one repair", result.TerminalReason, result.ResponseRepairs)
+	}
+	if len(repairer.problems) != 1 || !strings.HasPrefix(repairer.problems[0], "not valid JSON") {
+		t.Errorf("expected the repairer to be told the response is not JSON, got %v", repairer.problems)
+	}
+}
+
+func TestRepairResponse_RejectsRepairOutsideSchema(t *testing.T) {
+	repairer := &fakeRepairer{repaired: `{"action": "delete_everything"}`}
+	_, _, ok := repairResponse(context.Background(), repairer, "p1", true, `{"action": "done"`, errors.New("unexpected end of JSON input"), func(data []byte) (*actions.ActionEnvelope, error) {
+		t.Fatal("a repair failing the schema should not be parsed")
+		return nil, nil
+	})
+	if ok {
+		t.Error("expected a repair with an unknown action to be rejected")
+	}
+}
@@ -0,0 +1,310 @@
+package worker
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+const (
+	// summarizeThreshold is the share of the context window at which the
+	// action loop starts folding older turns into the rolling summary
+	summarizeThreshold = 0.7
+
+	// summarizeTarget is the share of the context window the loop's
+	// messages are brought down to when they are folded
+	summarizeTarget = 0.5
+
+	// contextShrinkFactor lowers the summarizer's idea of the context
+	// window below a request the provider rejected as too long, since the
+	// token estimate evidently undercounts for this model
+	contextShrinkFactor = 0.75
+
+	// maxSummaryRetries is how many times a rejected request is retried
+	// with more turns folded before falling back to truncation
+	maxSummaryRetries = 2
+
+	// minVerbatimMessages is the number of most recent messages never
+	// folded: the last response and the results fed back for it
+	minVerbatimMessages = 2
+
+	// summaryReserveTokens is room left for the summary to grow as turns
+	// are folded into it
+	summaryReserveTokens = 200
+
+	maxCarriedSummaryLen = 4000
+	maxSummaryFiles      = 40
+
+	rollingSummaryPrefix = "[Rolling summary of earlier turns"
+)
+
+// conversationSummarizer keeps an action loop's conversation within the
+// model's context window. Once the messages near the window, the oldest
+// turns after the dispatch's task are folded into one rolling summary of
+// the actions taken, the state each file was left in, the decisions noted
+// and the latest errors, so the model keeps what matters instead of losing
+// it to truncation.
+type conversationSummarizer struct {
+	limit   int    // estimated tokens the model accepts
+	task    string // the dispatch's task message, kept verbatim
+	carried string // a summary restored from a checkpoint
+	folded  int    // messages folded so far
+
+	digest    *segmentDigest
+	files     map[string]string
+	fileOrder []string
+}
+
+// newConversationSummarizer returns a summarizer for a loop starting with
+// messages. The task message is the last user message starting with the
+// task description, and a rolling summary left by an interrupted loop is
+// carried into the new one.
+func newConversationSummarizer(contextWindow int, messages []provider.ChatMessage, taskDescription string) *conversationSummarizer {
+	s := &conversationSummarizer{
+		limit: contextWindow,
+		files: make(map[string]string),
+	}
+	for i := len(messages) - 1; i >= 0; i-- {
+		m := messages[i]
+		if s.carried == "" && m.Role == "system" && isRollingSummary(m.Content) {
+			header, body, _ := strings.Cut(m.Content, "\n")
+			fmt.Sscanf(header, rollingSummaryPrefix+" (%d messages", &s.folded)
+			if s.carried = strings.TrimSpace(body); len(s.carried) > maxCarriedSummaryLen {
+				s.carried = s.carried[:maxCarriedSummaryLen] + "\n..."
+			}
+		}
+		if s.task == "" && m.Role == "user" && taskDescription != "" && strings.HasPrefix(m.Content, taskDescription) {
+			s.task = m.Content
+		}
+	}
+	return s
+}
+
+// compact folds the oldest turns into the summary once messages take more
+// than summarizeThreshold of the context window
+func (s *conversationSummarizer) compact(messages []provider.ChatMessage) []provider.ChatMessage {
+	size := messageTokens(messages)
+	if size <= int(float64(s.limit)*summarizeThreshold) {
+		return messages
+	}
+	out := s.fold(messages, int(float64(s.limit)*summarizeTarget))
+	if len(out) < len(messages) {
+		log.Printf("[ActionLoop] Summarized older turns to stay within the context window (%d -> %d messages, ~%d -> ~%d tokens)",
+			len(messages), len(out), size, messageTokens(out))
+	}
+	return out
+}
+
+// shrink handles the provider rejecting messages as too long: it lowers
+// the limit below their estimated size and folds more turns to fit. It
+// reports false when there was nothing left to fold.
+func (s *conversationSummarizer) shrink(messages []provider.ChatMessage) ([]provider.ChatMessage, bool) {
+	if lowered := int(float64(messageTokens(messages)) * contextShrinkFactor); lowered < s.limit {
+		s.limit = lowered
+	}
+	before := s.folded
+	out := s.fold(messages, int(float64(s.limit)*summarizeTarget))
+	return out, s.folded > before
+}
+
+// fold replaces the oldest turns after the task with the rolling summary,
+// keeping the most recent turns verbatim within target tokens
+func (s *conversationSummarizer) fold(messages []provider.ChatMessage, target int) []provider.ChatMessage {
+	pinned, body := s.split(messages)
+	budget := target - messageTokens(pinned) - estimateTokens(s.render()) - summaryReserveTokens
+
+	// Keep the newest messages that fit, always at least the last turn
+	maxCut := len(body) - minVerbatimMessages
+	if maxCut <= 0 {
+		return messages
+	}
+	cut := len(body)
+	used := 0
+	for cut > 0 && used+estimateTokens(body[cut-1].Content) <= budget {
+		used += estimateTokens(body[cut-1].Content)
+		cut--
+	}
+	if cut > maxCut {
+		cut = maxCut
+	}
+	// Fold whole turns so results stay with the response they answer
+	cut = s.turnStart(body, cut, maxCut)
+	if cut == 0 {
+		return messages
+	}
+
+	// The summary grows as turns are folded into it, so keep folding turns
+	// until everything fits or only the last turn is left
+	folded := 0
+	for {
+		s.absorb(body[folded:cut])
+		folded = cut
+		out := append([]provider.ChatMessage{}, pinned...)
+		out = append(out, provider.ChatMessage{Role: "system", Content: s.render()})
+		out = append(out, body[cut:]...)
+		if cut >= maxCut || messageTokens(out) <= target {
+			return out
+		}
+		cut = s.turnStart(body, cut+1, maxCut)
+	}
+}
+
+// turnStart moves cut forward to the next response, no further than maxCut
+func (s *conversationSummarizer) turnStart(body []provider.ChatMessage, cut, maxCut int) int {
+	for cut < maxCut && body[cut].Role != "assistant" {
+		cut++
+	}
+	return cut
+}
+
+// split separates the messages kept ahead of the summary, up to and
+// including the task, from the turns after it, dropping the old summary
+func (s *conversationSummarizer) split(messages []provider.ChatMessage) (pinned, body []provider.ChatMessage) {
+	for i, m := range messages {
+		if m.Role == "system" && isRollingSummary(m.Content) {
+			return messages[:i], messages[i+1:]
+		}
+	}
+	end := 0
+	for i := len(messages) - 1; i >= 0 && s.task != ""; i-- {
+		if messages[i].Role == "user" && messages[i].Content == s.task {
+			end = i + 1
+			break
+		}
+	}
+	// The task was truncated away: only the system prompt stays ahead
+	if end == 0 && len(messages) > 0 {
+		end = 1
+	}
+	return messages[:end], messages[end:]
+}
+
+// absorb folds messages into the summary
+func (s *conversationSummarizer) absorb(msgs []provider.ChatMessage) {
+	history := make([]models.ChatMessage, len(msgs))
+	for i, m := range msgs {
+		history[i] = models.ChatMessage{Role: m.Role, Content: m.Content}
+		if m.Role == "assistant" {
+			s.recordFileStates(m.Content)
+		}
+	}
+	d := digestSegment(historySegment{messages: history})
+	if s.digest == nil {
+		s.digest = &segmentDigest{actions: make(map[string]int)}
+	}
+	s.digest.merge(d)
+	// Unlike a condensed history digest, the summary keeps the latest errors
+	for _, e := range d.errors {
+		s.digest.errors = appendCapped(s.digest.errors, e, maxDigestErrors)
+	}
+	s.folded += len(msgs)
+}
+
+// recordFileStates notes the last thing each action in a response did to
+// a file. A file once changed stays changed when it is read again.
+func (s *conversationSummarizer) recordFileStates(content string) {
+	env, err := actions.DecodeLenient([]byte(content))
+	if err != nil {
+		env, err = actions.ParseSimpleJSON([]byte(content))
+	}
+	if err != nil || env == nil {
+		return
+	}
+	for _, a := range withEdits(env.Actions) {
+		if a.Path == "" {
+			continue
+		}
+		var state string
+		switch {
+		case isFileChange(a.Type):
+			state = "modified (" + a.Type + ")"
+		case a.Type == actions.ActionDeleteFile:
+			state = "deleted"
+		case a.Type == actions.ActionMoveFile || a.Type == actions.ActionRenameFile:
+			state = "moved"
+		case a.Type == actions.ActionReadCode || a.Type == actions.ActionReadFile:
+			if _, seen := s.files[a.Path]; seen {
+				continue
+			}
+			state = "read"
+		default:
+			continue
+		}
+		if _, seen := s.files[a.Path]; !seen {
+			s.fileOrder = append(s.fileOrder, a.Path)
+		}
+		s.files[a.Path] = state
+	}
+}
+
+// render writes the rolling summary message
+func (s *conversationSummarizer) render() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s (%d messages folded)]\n", rollingSummaryPrefix, s.folded)
+	if s.carried != "" {
+		sb.WriteString(s.carried + "\n")
+	}
+	d := s.digest
+	if d == nil {
+		return sb.String()
+	}
+
+	if len(d.actions) > 0 {
+		types := make([]string, 0, len(d.actions))
+		for t := range d.actions {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		parts := make([]string, len(types))
+		for i, t := range types {
+			parts[i] = fmt.Sprintf("%s x%d", t, d.actions[t])
+		}
+		sb.WriteString("Actions: " + strings.Join(parts, ", ") + "\n")
+	}
+	if len(s.fileOrder) > 0 {
+		sb.WriteString("File states:\n")
+		files := s.fileOrder
+		if len(files) > maxSummaryFiles {
+			fmt.Fprintf(&sb, "- (%d earlier files omitted)\n", len(files)-maxSummaryFiles)
+			files = files[len(files)-maxSummaryFiles:]
+		}
+		for _, f := range files {
+			sb.WriteString("- " + f + ": " + s.files[f] + "\n")
+		}
+	}
+	if len(d.decisions) > 0 {
+		sb.WriteString("Key decisions:\n")
+		for _, dec := range d.decisions {
+			sb.WriteString("- " + dec + "\n")
+		}
+	}
+	if len(d.errors) > 0 {
+		sb.WriteString("Recent errors:\n")
+		for _, e := range d.errors {
+			sb.WriteString("- " + e + "\n")
+		}
+	}
+	if d.outcome != "" {
+		sb.WriteString("Last action: " + d.outcome + "\n")
+	}
+	return sb.String()
+}
+
+// isRollingSummary reports whether a message is the summary written by a
+// conversationSummarizer
+func isRollingSummary(content string) bool {
+	return strings.HasPrefix(content, rollingSummaryPrefix)
+}
+
+func messageTokens(msgs []provider.ChatMessage) int {
+	n := 0
+	for _, m := range msgs {
+		n += estimateTokens(m.Content)
+	}
+	return n
+}
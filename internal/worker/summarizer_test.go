@@ -0,0 +1,165 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jordanhubbard/loom/internal/provider"
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+// buildLoopMessages simulates an action loop of n iterations after a
+// system prompt and task, each editing a file and reading another
+func buildLoopMessages(n int) []provider.ChatMessage {
+	msgs := []provider.ChatMessage{
+		{Role: "system", Content: "You are an agent."},
+		{Role: "user", Content: "Fix the parser\n\nContext:\nproject facts"},
+	}
+	for i := 1; i <= n; i++ {
+		msgs = append(msgs,
+			provider.ChatMessage{Role: "assistant", Content: fmt.Sprintf(
+				`{"actions":[{"type":"read_code","path":"pkg/read%d.go"},{"type":"edit_code","path":"pkg/file%d.go","patch":"-a\n+b"}],"notes":"Iteration %d: patch file%d because %s"}`,
+				i, i, i, i, strings.Repeat("tokens are dropped ", 10))},
+			provider.ChatMessage{Role: "user", Content: fmt.Sprintf(
+				"## Your Progress\nIteration %d/25 | 1 errors\n\nerror: build failed in file%d.go\n%s", i, i, strings.Repeat("output line\n", 40))},
+		)
+	}
+	return msgs
+}
+
+func TestConversationSummarizer_LeavesShortConversations(t *testing.T) {
+	msgs := buildLoopMessages(2)
+	s := newConversationSummarizer(1_000_000, msgs, "Fix the parser")
+	if got := s.compact(msgs); len(got) != len(msgs) {
+		t.Errorf("expected %d messages unchanged, got %d", len(msgs), len(got))
+	}
+}
+
+func TestConversationSummarizer_FoldsOlderTurns(t *testing.T) {
+	msgs := buildLoopMessages(10)
+	window := messageTokens(msgs) // the conversation fills the window
+	s := newConversationSummarizer(window, msgs, "Fix the parser")
+
+	got := s.compact(msgs)
+	if messageTokens(got) > int(float64(window)*summarizeTarget) {
+		t.Errorf("expected at most %d tokens, got %d", int(float64(window)*summarizeTarget), messageTokens(got))
+	}
+	if got[0].Content != msgs[0].Content || got[1].Content != msgs[1].Content {
+		t.Error("expected the system prompt and task kept verbatim")
+	}
+	if !isRollingSummary(got[2].Content) {
+		t.Fatalf("expected the rolling summary after the task, got %q", got[2].Content)
+	}
+	if last := got[len(got)-1]; last.Content != msgs[len(msgs)-1].Content {
+		t.Error("expected the latest results kept verbatim")
+	}
+	if got[3].Role != "assistant" {
+		t.Errorf("expected verbatim turns to start with a response, got %s", got[3].Role)
+	}
+
+	summary := got[2].Content
+	for _, want := range []string{
+		"pkg/file1.go: modified (edit_code)",
+		"pkg/read1.go: read",
+		"Key decisions:",
+		"patch file",
+		"error: build failed in file",
+		"edit_code x",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("expected summary to contain %q:\n%s", want, summary)
+		}
+	}
+	if strings.Contains(summary, "Iteration 1/25") {
+		t.Error("expected the progress line not to be reported as an error")
+	}
+
+	// More turns roll into the same summary
+	folded := s.folded
+	more := append(got, buildLoopMessages(20)[2+20:]...)
+	again := s.compact(more)
+	summaries := 0
+	for _, m := range again {
+		if isRollingSummary(m.Content) {
+			summaries++
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("expected one rolling summary, got %d", summaries)
+	}
+	if s.folded <= folded || !strings.Contains(again[2].Content, "pkg/file1.go") {
+		t.Errorf("expected earlier file states kept as more turns are folded:\n%s", again[2].Content)
+	}
+}
+
+func TestConversationSummarizer_CarriesCheckpointSummary(t *testing.T) {
+	msgs := buildLoopMessages(10)
+	s := newConversationSummarizer(messageTokens(msgs), msgs, "Fix the parser")
+	restored := append(s.compact(msgs), provider.ChatMessage{Role: "user", Content: resumeNotice(10)})
+
+	r := newConversationSummarizer(messageTokens(msgs), restored, "Fix the parser")
+	if r.folded != s.folded || !strings.Contains(r.carried, "pkg/file1.go") {
+		t.Fatalf("expected the restored summary carried over, got %d folded, %q", r.folded, r.carried)
+	}
+	out, ok := r.shrink(append(restored, buildLoopMessages(12)[2+20:]...))
+	if !ok {
+		t.Fatal("expected turns folded")
+	}
+	if out[1].Content != msgs[1].Content || !strings.Contains(out[2].Content, "pkg/file1.go") {
+		t.Errorf("expected the task kept and the carried summary rendered, got %q", out[2].Content)
+	}
+}
+
+// contextLimitProvider rejects requests longer than limit characters
+type contextLimitProvider struct {
+	limit    int
+	requests [][]provider.ChatMessage
+}
+
+func (p *contextLimitProvider) CreateChatCompletion(_ context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, error) {
+	p.requests = append(p.requests, req.Messages)
+	size := 0
+	for _, m := range req.Messages {
+		size += len(m.Content)
+	}
+	if size > p.limit {
+		return nil, &provider.ContextLengthError{StatusCode: 400, Body: "maximum context length exceeded"}
+	}
+	return (&sequenceMockProvider{responses: []string{`{"action":"done"}`}}).CreateChatCompletion(context.Background(), req)
+}
+
+func (p *contextLimitProvider) GetModels(context.Context) ([]provider.Model, error) {
+	return nil, nil
+}
+
+func TestCallWithContextRetry_SummarizesBeforeTruncating(t *testing.T) {
+	msgs := buildLoopMessages(10)
+	size := messageTokens(msgs) * 4
+	prov := &contextLimitProvider{limit: size * 2 / 3}
+	w := NewWorker("w1", &models.Agent{ID: "a1"}, &provider.RegisteredProvider{
+		Config:   &provider.ProviderConfig{ID: "p1", Model: "m", ContextWindow: messageTokens(msgs) * 2},
+		Protocol: prov,
+	})
+
+	s := newConversationSummarizer(w.getModelTokenLimit(), msgs, "Fix the parser")
+	resp, used, err := w.callWithContextRetry(context.Background(), &provider.ChatCompletionRequest{Messages: msgs}, s)
+	if err != nil || resp == nil {
+		t.Fatalf("expected the summarized retry to succeed, got %v", err)
+	}
+	if len(prov.requests) != 2 {
+		t.Errorf("expected one retry, got %d requests", len(prov.requests))
+	}
+	if used[1].Content != msgs[1].Content || !isRollingSummary(used[2].Content) {
+		t.Error("expected the task kept and older turns summarized")
+	}
+	for _, m := range used {
+		if strings.Contains(m.Content, "older messages dropped") {
+			t.Error("expected no truncation")
+		}
+	}
+	if s.limit >= w.getModelTokenLimit() {
+		t.Errorf("expected the rejection to lower the limit, still %d", s.limit)
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jordanhubbard/loom/internal/actions"
+	"github.com/jordanhubbard/loom/internal/artifacts"
 	"github.com/jordanhubbard/loom/internal/database"
 	"github.com/jordanhubbard/loom/internal/memory"
 	"github.com/jordanhubbard/loom/internal/provider"
@@ -25,6 +26,7 @@ type Worker struct {
 	agent       *models.Agent
 	provider    *provider.RegisteredProvider
 	db          *database.Database
+	budget      *ContextBudget
 	textMode    bool // Use simple text-based actions instead of JSON
 	status      WorkerStatus
 	currentTask string
@@ -33,6 +35,8 @@ type Worker struct {
 	ctx         context.Context
 	cancel      context.CancelFunc
 	mu          sync.RWMutex
+
+	schemaUnsupported bool // The provider refused a response schema; use plain JSON mode
 }
 
 // WorkerStatus represents the status of a worker
@@ -99,6 +103,19 @@ func (w *Worker) SetDatabase(db *database.Database) {
 	w.db = db
 }
 
+// SetContextBudget sets how prompts are sized for the worker's model
+func (w *Worker) SetContextBudget(b *ContextBudget) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.budget = b
+}
+
+func (w *Worker) contextBudget() *ContextBudget {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.budget
+}
+
 // ExecuteTask executes a task using the agent's persona and provider
 // Supports multi-turn conversations when ConversationSession is provided or database is available
 func (w *Worker) ExecuteTask(ctx context.Context, task *Task) (*TaskResult, error) {
@@ -190,7 +207,7 @@ func (w *Worker) ExecuteTask(ctx context.Context, task *Task) (*TaskResult, erro
 	}
 
 	// Send request to provider (with automatic context-length retry)
-	resp, usedMessages, err := w.callWithContextRetry(ctx, req)
+	resp, usedMessages, err := w.callWithContextRetry(ctx, req, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get completion: %w", err)
 	}
@@ -204,6 +221,10 @@ func (w *Worker) ExecuteTask(ctx context.Context, task *Task) (*TaskResult, erro
 	if conversationCtx != nil && w.db != nil {
 		// Convert provider messages back to conversation messages
 		for _, msg := range usedMessages {
+			// Digests of earlier dispatches are derived, not history
+			if isHistoryDigest(msg.Content) {
+				continue
+			}
 			// Only add new messages (not already in history)
 			if len(conversationCtx.Messages) == 0 ||
 			   !w.messageExists(conversationCtx.Messages, msg.Content) {
@@ -247,13 +268,10 @@ func (w *Worker) buildConversationMessages(conversationCtx *models.ConversationC
 		conversationCtx.AddMessage("system", systemPrompt, len(systemPrompt)/4)
 	}
 
-	// Convert conversation messages to provider messages
-	for _, msg := range conversationCtx.Messages {
-		messages = append(messages, provider.ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		})
-	}
+	// Convert conversation messages to provider messages, summarizing
+	// earlier dispatches as needed so this one still fits
+	messages = append(messages, w.compressConversation(conversationCtx)...)
+	markDispatchStart(conversationCtx)
 
 	// Append new user message
 	userPrompt := task.Description
@@ -340,15 +358,18 @@ func (w *Worker) handleTokenLimits(messages []provider.ChatMessage) []provider.C
 	return messages
 }
 
+// compressConversation returns a bead's conversation history within the
+// share of the model's context window reserved for prior dispatches
+func (w *Worker) compressConversation(c *models.ConversationContext) []provider.ChatMessage {
+	budget := int(float64(w.getModelTokenLimit()) * historyBudgetFraction)
+	return compressHistory(c.Messages, dispatchBoundaries(c), budget)
+}
+
 // getModelTokenLimit returns the token limit for the current model.
 // Uses the provider's discovered context window (from heartbeat) if available,
-// falling back to a conservative default.
+// then the model's metadata, falling back to a conservative default.
 func (w *Worker) getModelTokenLimit() int {
-	if w.provider.Config.ContextWindow > 0 {
-		return w.provider.Config.ContextWindow
-	}
-	// Conservative default if heartbeat hasn't discovered the context window yet
-	return 32768
+	return w.contextBudget().contextWindow(w.provider.Config)
 }
 
 // truncateMessages drops older conversation messages to reduce token count.
@@ -390,11 +411,13 @@ func truncateMessages(messages []provider.ChatMessage, fraction float64) []provi
 }
 
 // callWithContextRetry calls CreateChatCompletion and retries with
-// progressively smaller message windows on ContextLengthError.
+// progressively smaller message windows on ContextLengthError. With a
+// summarizer, older turns are first folded into its rolling summary, and
+// messages are only truncated if that is not enough.
 // Returns the response and the final messages used (which may be truncated).
-func (w *Worker) callWithContextRetry(ctx context.Context, req *provider.ChatCompletionRequest) (*provider.ChatCompletionResponse, []provider.ChatMessage, error) {
+func (w *Worker) callWithContextRetry(ctx context.Context, req *provider.ChatCompletionRequest, summarizer *conversationSummarizer) (*provider.ChatCompletionResponse, []provider.ChatMessage, error) {
 	// Attempt 1: use messages as-is
-	resp, err := w.provider.Protocol.CreateChatCompletion(ctx, req)
+	resp, err := w.createCompletion(ctx, req)
 	if err == nil {
 		return resp, req.Messages, nil
 	}
@@ -404,10 +427,31 @@ func (w *Worker) callWithContextRetry(ctx context.Context, req *provider.ChatCom
 		return nil, req.Messages, err
 	}
 
+	messages := req.Messages
+	for attempt := 0; summarizer != nil && attempt < maxSummaryRetries; attempt++ {
+		summarized, ok := summarizer.shrink(messages)
+		if !ok {
+			break
+		}
+		log.Printf("[ContextRetry] Retrying with older turns summarized (%d -> %d messages)",
+			len(messages), len(summarized))
+
+		retryReq := *req
+		retryReq.Messages = summarized
+
+		resp, err = w.provider.CreateChatCompletion(ctx, &retryReq)
+		if err == nil {
+			return resp, summarized, nil
+		}
+		if !errors.As(err, &ctxErr) {
+			return nil, summarized, err
+		}
+		messages = summarized
+	}
+
 	// Retry with progressively smaller context windows.
 	// Each attempt keeps a smaller fraction of the conversation history.
 	fractions := []float64{0.5, 0.25, 0.0}
-	messages := req.Messages
 
 	for _, frac := range fractions {
 		truncated := truncateMessages(messages, frac)
@@ -417,7 +461,7 @@ func (w *Worker) callWithContextRetry(ctx context.Context, req *provider.ChatCom
 		retryReq := *req
 		retryReq.Messages = truncated
 
-		resp, err = w.provider.Protocol.CreateChatCompletion(ctx, &retryReq)
+		resp, err = w.provider.CreateChatCompletion(ctx, &retryReq)
 		if err == nil {
 			return resp, truncated, nil
 		}
@@ -438,7 +482,7 @@ func (w *Worker) callWithContextRetry(ctx context.Context, req *provider.ChatCom
 
 			retryReq := *req
 			retryReq.Messages = minimal
-			resp, err = w.provider.Protocol.CreateChatCompletion(ctx, &retryReq)
+			resp, err = w.provider.CreateChatCompletion(ctx, &retryReq)
 			if err == nil {
 				return resp, minimal, nil
 			}
@@ -448,6 +492,19 @@ func (w *Worker) callWithContextRetry(ctx context.Context, req *provider.ChatCom
 	return nil, minimal, fmt.Errorf("context length exceeded after all retry attempts: %w", err)
 }
 
+// sameMessages reports whether two message lists are identical
+func sameMessages(a, b []provider.ChatMessage) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Role != b[i].Role || a[i].Content != b[i].Content {
+			return false
+		}
+	}
+	return true
+}
+
 // messageExists checks if a message with the same content already exists in history
 func (w *Worker) messageExists(messages []models.ChatMessage, content string) bool {
 	for _, msg := range messages {
@@ -470,21 +527,7 @@ func (w *Worker) buildSystemPrompt() string {
 	}
 
 	// 2. Brief persona role context
-	persona := w.agent.Persona
-	if persona == nil {
-		prompt += fmt.Sprintf("# Your Role\nYou are %s. Act on the task given to you.\n\n", w.agent.Name)
-	} else {
-		prompt += "# Your Role\n"
-		if persona.Character != "" {
-			prompt += persona.Character + "\n"
-		} else {
-			prompt += fmt.Sprintf("You are %s.\n", w.agent.Name)
-		}
-		if persona.Mission != "" {
-			prompt += "Mission: " + persona.Mission + "\n"
-		}
-		prompt += "\n"
-	}
+	prompt += w.personaPrompt()
 
 	return prompt
 }
@@ -518,9 +561,11 @@ type Task struct {
 	ID                  string
 	Description         string
 	Context             string
+	Files               string // File contents and listings, e.g. a repository overview; trimmed first when the prompt is over budget
 	BeadID              string
 	ProjectID           string
 	ConversationSession *models.ConversationContext // Optional: enables multi-turn conversation
+	PlanOnly            bool                        // Plan mode: perform only reads and return the plan of the rest
 }
 
 // TaskResult represents the result of task execution
@@ -534,8 +579,9 @@ type TaskResult struct {
 	CompletedAt        time.Time
 	Success            bool
 	Error              string
-	LoopIterations     int    // Set when action loop is used
-	LoopTerminalReason string // Set when action loop is used
+	LoopIterations     int                   // Set when action loop is used
+	LoopTerminalReason string                // Set when action loop is used
+	Plan               *models.ExecutionPlan // Set when a plan mode loop finished planning
 }
 
 // WorkerInfo contains information about a worker
@@ -559,22 +605,44 @@ type LessonsProvider interface {
 	RecordLesson(projectID, category, title, detail, beadID, agentID string) error
 }
 
+// FileExpertiseProvider supplies and records which files an agent has
+// successfully modified.
+type FileExpertiseProvider interface {
+	GetExpertiseForPrompt(projectID, agentID string) string
+	RecordFileEdits(projectID, agentID, personaName, beadID string, paths []string) error
+}
+
 // LoopConfig configures the multi-turn action loop.
 type LoopConfig struct {
 	MaxIterations   int
 	Router          *actions.Router
 	ActionContext   actions.ActionContext
 	LessonsProvider LessonsProvider
+	FileExpertise   FileExpertiseProvider
 	DB              *database.Database
+	Artifacts       *artifacts.Recorder
+	Guidance        GuidanceProvider
 	TextMode        bool // Use simple text-based actions (~10 commands) instead of JSON (60+)
+	MaxResumes      int  // Max times a bead's loop resumes from a checkpoint (0 = DefaultMaxResumes)
+	Repairer        ResponseRepairer
+
+	// ModelSwitcher moves the loop between model tiers mid-bead; nil keeps
+	// the worker's model throughout
+	ModelSwitcher ModelSwitcher
+	// DowngradeAfter is how many simple iterations in a row move the loop
+	// down a tier (0 = DefaultDowngradeAfter, negative = never)
+	DowngradeAfter int
 }
 
 // LoopResult contains the result of a multi-turn action loop.
 type LoopResult struct {
 	*TaskResult
 	Iterations     int              `json:"iterations"`
-	TerminalReason string           `json:"terminal_reason"` // "completed", "max_iterations", "escalated", "error", "no_actions", "parse_failures"
+	TerminalReason string           `json:"terminal_reason"` // "completed", "planned", "max_iterations", "escalated", "handed_off", "error", "no_actions", "parse_failures"
 	ActionLog      []ActionLogEntry `json:"action_log"`
+
+	// Responses that did not parse and were repaired to match the schema
+	ResponseRepairs int `json:"response_repairs,omitempty"`
 }
 
 // ActionLogEntry records a single iteration of the action loop.
@@ -582,7 +650,10 @@ type ActionLogEntry struct {
 	Iteration int              `json:"iteration"`
 	Actions   []actions.Action `json:"actions"`
 	Results   []actions.Result `json:"results"`
+	Guidance  []Guidance       `json:"guidance,omitempty"` // Comments from humans injected before this iteration
 	Timestamp time.Time        `json:"timestamp"`
+
+	ModelSwitch *ModelSwitch `json:"model_switch,omitempty"` // Model the loop moved to before this iteration
 }
 
 // isConversationalResponse detects when the model slips into chat mode
@@ -673,30 +744,10 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 		}
 	}
 
-	// Build system prompt with lessons
-	systemPrompt := w.buildEnhancedSystemPrompt(config.LessonsProvider, task.ProjectID, task.Context)
-
+	// Build the system prompt, earlier history and task within the prompt budget
+	messages, lessonIDs := w.buildDispatchMessages(config, task, conversationCtx)
 	if conversationCtx != nil {
-		if len(conversationCtx.Messages) == 0 {
-			conversationCtx.AddMessage("system", systemPrompt, len(systemPrompt)/4)
-		}
-		for _, msg := range conversationCtx.Messages {
-			messages = append(messages, provider.ChatMessage{Role: msg.Role, Content: msg.Content})
-		}
-		userPrompt := task.Description
-		if task.Context != "" {
-			userPrompt = fmt.Sprintf("%s\n\nContext:\n%s", userPrompt, task.Context)
-		}
-		messages = append(messages, provider.ChatMessage{Role: "user", Content: userPrompt})
-	} else {
-		userPrompt := task.Description
-		if task.Context != "" {
-			userPrompt = fmt.Sprintf("%s\n\nContext:\n%s", userPrompt, task.Context)
-		}
-		messages = []provider.ChatMessage{
-			{Role: "system", Content: systemPrompt},
-			{Role: "user", Content: userPrompt},
-		}
+		markDispatchStart(conversationCtx)
 	}
 
 	loopResult := &LoopResult{
@@ -715,7 +766,36 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 	consecutiveValidationFailures := 0
 	actionHashes := make(map[string]int) // for inner loop detection
 
-	for iteration := 0; iteration < maxIter; iteration++ {
+	// Resume from a checkpoint left by an interrupted loop (e.g. restart)
+	startIteration := 0
+	resumeCount := 0
+	if cp := w.loadCheckpoint(config, task); cp != nil && cp.Iteration < maxIter {
+		log.Printf("[ActionLoop] Resuming task %s for bead %s from checkpoint at iteration %d (resume %d)",
+			task.ID, task.BeadID, cp.Iteration, cp.ResumeCount)
+		startIteration = cp.Iteration
+		resumeCount = cp.ResumeCount
+		notice := resumeNotice(cp.Iteration)
+		messages = append(cp.Messages, provider.ChatMessage{Role: "user", Content: notice})
+		if conversationCtx != nil {
+			conversationCtx.AddMessage("user", notice, len(notice)/4)
+		}
+		loopResult.ActionLog = cp.ActionLog
+		loopResult.TokensUsed = cp.TokensUsed
+		allActions = replayActionLog(cp.ActionLog, tracker)
+		for _, entry := range cp.ActionLog {
+			actionHashes[hashActions(entry.Actions)]++
+		}
+	}
+	defer w.finishCheckpoint(config, task, loopResult)
+	summarizer := newConversationSummarizer(w.getModelTokenLimit(), messages, task.Description)
+	guidance := newGuidanceInbox(time.Now())
+	tiering := newModelTiering(config, task)
+	if tiering != nil {
+		// Later tasks start on the worker's own model
+		defer w.useProvider(w.currentProvider())
+	}
+
+	for iteration := startIteration; iteration < maxIter; iteration++ {
 		select {
 		case <-ctx.Done():
 			loopResult.TerminalReason = "context_canceled"
@@ -726,19 +806,30 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 		default:
 		}
 
-		// Handle token limits
+		// Pass on comments humans posted on the bead since the last call
+		if iteration > startIteration {
+			messages = w.injectGuidance(config, task, guidance, iteration+1, messages, conversationCtx, loopResult)
+		}
+
+		// Fold older turns into the rolling summary as the conversation
+		// nears the context window, then truncate if it still does not fit
+		if tiering != nil {
+			// The loop may have moved to a model with another window
+			summarizer.limit = w.getModelTokenLimit()
+		}
+		messages = summarizer.compact(messages)
 		trimmedMessages := w.handleTokenLimits(messages)
 
 		req := &provider.ChatCompletionRequest{
 			Model:          w.provider.Config.Model,
 			Messages:       trimmedMessages,
 			Temperature:    0.7,
-			ResponseFormat: &provider.ResponseFormat{Type: "json_object"},
+			ResponseFormat: w.responseFormat(config.TextMode),
 		}
 
 		log.Printf("[ActionLoop] Iteration %d/%d for task %s (messages: %d, textMode: %v)", iteration+1, maxIter, task.ID, len(trimmedMessages), config.TextMode)
 
-		resp, usedMsgs, err := w.callWithContextRetry(ctx, req)
+		resp, usedMsgs, err := w.callWithContextRetry(ctx, req, summarizer)
 		if err != nil {
 			loopResult.TerminalReason = "error"
 			loopResult.Iterations = iteration + 1
@@ -748,8 +839,8 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 			loopResult.CompletedAt = time.Now()
 			return loopResult, fmt.Errorf("LLM call failed on iteration %d: %w", iteration+1, err)
 		}
-		// If messages were truncated by retry, update the working set
-		if len(usedMsgs) < len(trimmedMessages) {
+		// If messages were summarized or truncated by retry, update the working set
+		if !sameMessages(usedMsgs, trimmedMessages) {
 			messages = usedMsgs
 		}
 
@@ -766,6 +857,7 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 		llmResponse := resp.Choices[0].Message.Content
 		loopResult.Response = llmResponse
 		loopResult.TokensUsed += resp.Usage.TotalTokens
+		recordExchangeArtifacts(config, task, iteration+1, req, usedMsgs, llmResponse)
 
 		// Add assistant message to conversation
 		messages = append(messages, provider.ChatMessage{Role: "assistant", Content: llmResponse})
@@ -775,12 +867,19 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 
 		// Parse actions — text mode uses simple JSON parser (10 actions),
 		// legacy mode uses full JSON decoder (60+ actions)
-		var env *actions.ActionEnvelope
-		var parseErr error
+		parse := actions.DecodeLenient
 		if config.TextMode {
-			env, parseErr = actions.ParseSimpleJSON([]byte(llmResponse))
-		} else {
-			env, parseErr = actions.DecodeLenient([]byte(llmResponse))
+			parse = actions.ParseSimpleJSON
+		}
+		env, parseErr := parse([]byte(llmResponse))
+		if parseErr != nil && config.Repairer != nil {
+			if repaired, repairedEnv, ok := repairResponse(ctx, config.Repairer, task.ProjectID, config.TextMode, llmResponse, parseErr, parse); ok {
+				log.Printf("[ActionLoop] Repaired unparseable response on iteration %d", iteration+1)
+				llmResponse, env, parseErr = repaired, repairedEnv, nil
+				loopResult.Response = repaired
+				loopResult.ResponseRepairs++
+				messages[len(messages)-1].Content = repaired
+			}
 		}
 		if parseErr != nil {
 			var validationErr *actions.ValidationError
@@ -789,6 +888,9 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 				// Give specific feedback and let the model retry — don't count
 				// this as a hard parse failure.
 				consecutiveValidationFailures++
+				if consecutiveValidationFailures >= 4 && tiering.upgrade(w, loopResult, iteration+2, fmt.Sprintf("repeated validation failures: %v", validationErr)) {
+					consecutiveValidationFailures = 0
+				}
 				if consecutiveValidationFailures >= 4 {
 					loopResult.TerminalReason = "validation_failures"
 					loopResult.Iterations = iteration + 1
@@ -828,6 +930,9 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 			}
 
 			consecutiveParseFailures++
+			if consecutiveParseFailures >= 2 && tiering.upgrade(w, loopResult, iteration+2, fmt.Sprintf("two consecutive parse failures: %v", parseErr)) {
+				consecutiveParseFailures = 0
+			}
 			if consecutiveParseFailures >= 2 {
 				loopResult.TerminalReason = "parse_failures"
 				loopResult.Iterations = iteration + 1
@@ -858,8 +963,16 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 			return loopResult, nil
 		}
 
-		// Execute actions
-		results, execErr := config.Router.Execute(ctx, env, config.ActionContext)
+		// Execute actions; a plan mode loop only performs reads
+		var results []actions.Result
+		var execErr error
+		if task.PlanOnly {
+			results, execErr = executePlanMode(func(reads *actions.ActionEnvelope) ([]actions.Result, error) {
+				return config.Router.Execute(ctx, reads, config.ActionContext)
+			}, env)
+		} else {
+			results, execErr = config.Router.Execute(ctx, env, config.ActionContext)
+		}
 		if execErr != nil {
 			loopResult.TerminalReason = "error"
 			loopResult.Iterations = iteration + 1
@@ -872,6 +985,7 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 
 		allActions = append(allActions, results...)
 		tracker.Update(iteration+1, results)
+		recordTestLogArtifacts(config, task, iteration+1, results)
 
 		// Log the iteration
 		loopResult.ActionLog = append(loopResult.ActionLog, ActionLogEntry{
@@ -883,6 +997,12 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 
 		// Check for terminal actions
 		termReason := checkTerminalCondition(env, results)
+		if task.PlanOnly {
+			termReason = ""
+			if planTerminated(env) {
+				termReason = "planned"
+			}
+		}
 		if termReason != "" {
 			loopResult.TerminalReason = termReason
 			loopResult.Iterations = iteration + 1
@@ -922,6 +1042,11 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 		if actionHashes[hash] >= 5 {
 			log.Printf("[ActionLoop] Warning: same actions repeated %d times (hash %s)", actionHashes[hash], hash[:8])
 		}
+		if actionHashes[hash] == 5 {
+			tiering.upgrade(w, loopResult, iteration+2, "same actions repeated 5 times")
+		} else {
+			tiering.observe(w, loopResult, iteration+2, env, results)
+		}
 
 		// Format results as user message, prepended with progress summary
 		feedback := tracker.Summary(iteration+1) + actions.FormatResultsAsUserMessage(results)
@@ -930,6 +1055,9 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 			conversationCtx.AddMessage("user", feedback, len(feedback)/4)
 		}
 
+		// Checkpoint so the loop can resume here after a restart
+		w.saveCheckpoint(config, task, iteration+1, resumeCount, messages, loopResult)
+
 		// Persist conversation context periodically
 		if conversationCtx != nil && config.DB != nil && (iteration%3 == 2 || iteration == maxIter-1) {
 			if err := config.DB.UpdateConversationContext(conversationCtx); err != nil {
@@ -946,8 +1074,28 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 		loopResult.CompletedAt = time.Now()
 	}
 
-	// Extract lessons from the completed loop
-	if config.DB != nil && task.ProjectID != "" {
+	// Credit the agent with the files it changed on a successful run
+	if loopResult.TerminalReason == "completed" && config.FileExpertise != nil && task.ProjectID != "" {
+		if paths := modifiedFiles(loopResult.ActionLog); len(paths) > 0 {
+			if err := config.FileExpertise.RecordFileEdits(task.ProjectID, w.agent.ID, w.agent.PersonaName, task.BeadID, paths); err != nil {
+				log.Printf("[ActionLoop] Warning: Failed to record file expertise: %v", err)
+			}
+		}
+	}
+
+	if task.PlanOnly && loopResult.TerminalReason == "planned" {
+		plan := buildPlan(loopResult.ActionLog)
+		plan.PlanningTokens = loopResult.TokensUsed
+		plan.AgentID = w.agent.ID
+		loopResult.Plan = plan
+	}
+
+	// Credit the lessons in the prompt with how the dispatch ended
+	w.recordLessonOutcome(config, task, lessonIDs, loopResult.TerminalReason)
+
+	// Extract lessons from the completed loop; a plan changed nothing to
+	// learn from
+	if config.DB != nil && task.ProjectID != "" && !task.PlanOnly {
 		entries := flattenActionLog(loopResult.ActionLog)
 		if len(entries) > 0 {
 			extractor := memory.NewExtractor(config.DB, memory.NewHashEmbedder())
@@ -968,13 +1116,23 @@ func (w *Worker) ExecuteTaskWithLoop(ctx context.Context, task *Task, config *Lo
 // buildEnhancedSystemPrompt builds the system prompt with ReAct operating model first,
 // brief persona role second, and action format last.
 func (w *Worker) buildEnhancedSystemPrompt(lp LessonsProvider, projectID, progressCtx string) string {
-	// Get lessons — try file-based LESSONS.md first, then semantic search, then recency
+	lessons, _ := w.lessonsForPrompt(lp, projectID, progressCtx)
+	return w.operatingModelPrompt(lessons, progressCtx) + w.personaPrompt()
+}
+
+// lessonsForPrompt returns the project's lessons — file-based LESSONS.md
+// first, then semantic search, then recency — with the IDs of the stored
+// lessons among them when the provider reports them
+func (w *Worker) lessonsForPrompt(lp LessonsProvider, projectID, progressCtx string) (string, []string) {
 	var lessons string
 	if projectID != "" {
 		lessonsFile := actions.NewLessonsFile(".")
 		lessons = lessonsFile.GetLessonsForPrompt()
 	}
 	if lessons == "" && lp != nil && projectID != "" {
+		if rec, ok := lp.(LessonOutcomeRecorder); ok {
+			return rec.SelectLessons(projectID, progressCtx, 5)
+		}
 		// Use semantic retrieval if we have task context
 		if progressCtx != "" {
 			lessons = lp.GetRelevantLessons(projectID, progressCtx, 5)
@@ -983,34 +1141,36 @@ func (w *Worker) buildEnhancedSystemPrompt(lp LessonsProvider, projectID, progre
 			lessons = lp.GetLessonsForPrompt(projectID)
 		}
 	}
+	return lessons, nil
+}
 
-	// 1. Action format with ReAct pattern FIRST — this is the operating model
-	var prompt string
+// operatingModelPrompt returns the action format with the ReAct pattern —
+// the operating model that goes first in the system prompt
+func (w *Worker) operatingModelPrompt(lessons, progressCtx string) string {
 	if w.textMode {
-		prompt = actions.BuildSimpleJSONPrompt(lessons, progressCtx) + "\n\n"
-	} else {
-		prompt = actions.BuildEnhancedPrompt(lessons, progressCtx) + "\n\n"
+		return actions.BuildSimpleJSONPrompt(lessons, progressCtx) + "\n\n"
 	}
+	return actions.BuildEnhancedPrompt(lessons, progressCtx) + "\n\n"
+}
 
-	// 2. Brief persona role context — just enough for the model to know its specialization.
-	// NOT the verbose analysis instructions that override the ReAct action bias.
+// personaPrompt returns brief persona role context — just enough for the
+// model to know its specialization. NOT the verbose analysis instructions
+// that override the ReAct action bias.
+func (w *Worker) personaPrompt() string {
 	persona := w.agent.Persona
 	if persona == nil {
-		prompt += fmt.Sprintf("# Your Role\nYou are %s. Act on the task given to you.\n\n", w.agent.Name)
+		return fmt.Sprintf("# Your Role\nYou are %s. Act on the task given to you.\n\n", w.agent.Name)
+	}
+	prompt := "# Your Role\n"
+	if persona.Character != "" {
+		prompt += persona.Character + "\n"
 	} else {
-		prompt += "# Your Role\n"
-		if persona.Character != "" {
-			prompt += persona.Character + "\n"
-		} else {
-			prompt += fmt.Sprintf("You are %s.\n", w.agent.Name)
-		}
-		if persona.Mission != "" {
-			prompt += "Mission: " + persona.Mission + "\n"
-		}
-		prompt += "\n"
+		prompt += fmt.Sprintf("You are %s.\n", w.agent.Name)
 	}
-
-	return prompt
+	if persona.Mission != "" {
+		prompt += "Mission: " + persona.Mission + "\n"
+	}
+	return prompt + "\n"
 }
 
 // checkTerminalCondition checks if any action in the envelope signals termination.
@@ -1027,12 +1187,56 @@ func checkTerminalCondition(env *actions.ActionEnvelope, results []actions.Resul
 			return "completed"
 		case actions.ActionEscalateCEO:
 			return "escalated"
+		case actions.ActionHandoff:
+			if i < len(results) && results[i].Status == "error" {
+				continue // handoff refused, keep working
+			}
+			return "handed_off"
 		}
 	}
 	return ""
 }
 
 // recordBuildLessons checks action results for build/test failures and records lessons.
+// artifactRef identifies the dispatch a task's artifacts belong to
+func artifactRef(task *Task) artifacts.Ref {
+	return artifacts.Ref{DispatchID: task.ID, BeadID: task.BeadID, ProjectID: task.ProjectID}
+}
+
+// recordExchangeArtifacts stores the messages actually sent to the model on
+// one iteration and the response it returned
+func recordExchangeArtifacts(config *LoopConfig, task *Task, iteration int, req *provider.ChatCompletionRequest, sent []provider.ChatMessage, response string) {
+	if config.Artifacts == nil {
+		return
+	}
+	ref := artifactRef(task)
+	if _, err := config.Artifacts.RecordPrompt(ref, iteration, req, sent); err != nil {
+		log.Printf("[ActionLoop] Failed to record prompt artifact for task %s: %v", task.ID, err)
+	}
+	if _, err := config.Artifacts.Record(ref, artifacts.KindResponse, "", iteration, []byte(response)); err != nil {
+		log.Printf("[ActionLoop] Failed to record response artifact for task %s: %v", task.ID, err)
+	}
+}
+
+// recordTestLogArtifacts stores the output of every run_tests action
+func recordTestLogArtifacts(config *LoopConfig, task *Task, iteration int, results []actions.Result) {
+	if config.Artifacts == nil {
+		return
+	}
+	for _, r := range results {
+		if r.ActionType != actions.ActionRunTests {
+			continue
+		}
+		output, _ := r.Metadata["output"].(string)
+		if output == "" {
+			output = r.Message
+		}
+		if _, err := config.Artifacts.Record(artifactRef(task), artifacts.KindTestLog, r.ActionType, iteration, []byte(output)); err != nil {
+			log.Printf("[ActionLoop] Failed to record test log artifact for task %s: %v", task.ID, err)
+		}
+	}
+}
+
 func (w *Worker) recordBuildLessons(config *LoopConfig, env *actions.ActionEnvelope, results []actions.Result) {
 	if config.LessonsProvider == nil {
 		return
@@ -1117,6 +1321,46 @@ func flattenActionLog(log []ActionLogEntry) []memory.ActionEntry {
 	return entries
 }
 
+// modifiedFiles returns the paths changed by successful file-editing
+// actions in an action log, in first-touched order.
+func modifiedFiles(log []ActionLogEntry) []string {
+	seen := make(map[string]bool)
+	var paths []string
+	for _, entry := range log {
+		for i, a := range entry.Actions {
+			if i >= len(entry.Results) || entry.Results[i].Status != "executed" {
+				continue
+			}
+			var touched []string
+			switch a.Type {
+			case actions.ActionWriteFile, actions.ActionEditCode, actions.ActionApplyPatch,
+				actions.ActionExtractMethod, actions.ActionRenameSymbol, actions.ActionInlineVariable:
+				if a.Path == "" {
+					continue
+				}
+				touched = []string{a.Path}
+			case actions.ActionEditTransaction:
+			default:
+				continue
+			}
+			if a.Type == actions.ActionRenameSymbol || a.Type == actions.ActionEditTransaction {
+				// A rename edits every file that uses the symbol, a transaction
+				// every file of its edits
+				if files, ok := entry.Results[i].Metadata["files"].([]string); ok {
+					touched = append(touched, files...)
+				}
+			}
+			for _, path := range touched {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+	return paths
+}
+
 // hashActions computes a deterministic hash of action types and key fields.
 func hashActions(acts []actions.Action) string {
 	var sb strings.Builder
@@ -252,6 +252,18 @@ func TestCheckTerminalCondition(t *testing.T) {
 			results: []actions.Result{{ActionType: actions.ActionEscalateCEO, Status: "executed"}},
 			want:    "escalated",
 		},
+		{
+			name:    "handoff action",
+			env:     &actions.ActionEnvelope{Actions: []actions.Action{{Type: actions.ActionHandoff}}},
+			results: []actions.Result{{ActionType: actions.ActionHandoff, Status: "executed"}},
+			want:    "handed_off",
+		},
+		{
+			name:    "handoff refused",
+			env:     &actions.ActionEnvelope{Actions: []actions.Action{{Type: actions.ActionHandoff}}},
+			results: []actions.Result{{ActionType: actions.ActionHandoff, Status: "error"}},
+			want:    "",
+		},
 		{
 			name:    "non-terminal action",
 			env:     &actions.ActionEnvelope{Actions: []actions.Action{{Type: actions.ActionReadCode}}},
@@ -447,6 +459,68 @@ func TestFlattenActionLog_Empty(t *testing.T) {
 	}
 }
 
+func TestModifiedFiles(t *testing.T) {
+	log := []ActionLogEntry{
+		{
+			Iteration: 1,
+			Actions: []actions.Action{
+				{Type: actions.ActionReadCode, Path: "main.go"},
+				{Type: actions.ActionEditCode, Path: "internal/auth.go"},
+				{Type: actions.ActionWriteFile, Path: "internal/broken.go"},
+			},
+			Results: []actions.Result{
+				{ActionType: actions.ActionReadCode, Status: "executed"},
+				{ActionType: actions.ActionEditCode, Status: "executed"},
+				{ActionType: actions.ActionWriteFile, Status: "error"},
+			},
+		},
+		{
+			Iteration: 2,
+			Actions: []actions.Action{
+				{Type: actions.ActionEditCode, Path: "internal/auth.go"},
+				{Type: actions.ActionWriteFile, Path: "internal/auth_test.go"},
+			},
+			Results: []actions.Result{
+				{ActionType: actions.ActionEditCode, Status: "executed"},
+				{ActionType: actions.ActionWriteFile, Status: "executed"},
+			},
+		},
+		{
+			Iteration: 3,
+			Actions: []actions.Action{
+				{Type: actions.ActionRenameSymbol, Path: "internal/auth.go", Symbol: "check", NewName: "verify"},
+			},
+			Results: []actions.Result{
+				{ActionType: actions.ActionRenameSymbol, Status: "executed", Metadata: map[string]interface{}{
+					"files": []string{"internal/auth.go", "internal/api/login.go"},
+				}},
+			},
+		},
+		{
+			Iteration: 4,
+			Actions: []actions.Action{
+				{Type: actions.ActionEditTransaction, Edits: []actions.Action{{Type: actions.ActionWriteFile, Path: "internal/session.go"}}},
+			},
+			Results: []actions.Result{
+				{ActionType: actions.ActionEditTransaction, Status: "executed", Metadata: map[string]interface{}{
+					"files": []string{"internal/session.go", "internal/auth.go"},
+				}},
+			},
+		},
+	}
+
+	got := modifiedFiles(log)
+	want := []string{"internal/auth.go", "internal/auth_test.go", "internal/api/login.go", "internal/session.go"}
+	if len(got) != len(want) {
+		t.Fatalf("modifiedFiles = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("modifiedFiles[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -1,9 +1,9 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"log"
-	"os"
 
 	"github.com/jordanhubbard/loom/pkg/config"
 	"github.com/jordanhubbard/loom/pkg/server"
@@ -13,29 +13,24 @@ func main() {
 	fmt.Println("Welcome to Loom - AI Coding Agent Orchestrator")
 	fmt.Println("==================================================")
 
-	// Load configuration from config.yaml if it exists, otherwise use defaults
-	configPath := os.Getenv("CONFIG_PATH")
-	if configPath == "" {
-		configPath = "config.yaml"
-	}
+	configPath := flag.String("config", "", "Path to a YAML or TOML configuration file")
+	flag.Parse()
 
-	cfg, err := config.LoadConfigFromFile(configPath)
+	// Load the config file named with -config, LOOM_CONFIG or CONFIG_PATH,
+	// or the first found on the search path, over the defaults; LOOM_
+	// environment variables override both
+	path, err := config.Find(*configPath)
 	if err != nil {
-		log.Printf("Warning: Failed to load config from %s: %v", configPath, err)
-		log.Printf("Using default configuration")
-		cfg = config.DefaultConfig()
-	} else {
-		log.Printf("Loaded configuration from %s", configPath)
+		log.Fatalf("%v", err)
 	}
-
-	// Override with environment variables if set
-	if temporalHost := os.Getenv("TEMPORAL_HOST"); temporalHost != "" {
-		cfg.Temporal.Host = temporalHost
-		log.Printf("Using Temporal host from environment: %s", temporalHost)
+	cfg, err := config.Load(path)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
 	}
-	if temporalNamespace := os.Getenv("TEMPORAL_NAMESPACE"); temporalNamespace != "" {
-		cfg.Temporal.Namespace = temporalNamespace
-		log.Printf("Using Temporal namespace from environment: %s", temporalNamespace)
+	if path == "" {
+		log.Printf("No config file found; using defaults and environment")
+	} else {
+		log.Printf("Loaded configuration from %s", path)
 	}
 
 	fmt.Println("\nLoom Worker System initialized")
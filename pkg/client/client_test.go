@@ -0,0 +1,119 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+)
+
+func TestCreateBead(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v1/beads" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer key-123" {
+			t.Errorf("expected bearer API key, got %q", got)
+		}
+		var req models.CreateBeadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(models.Bead{ID: "bd-1", Title: req.Title, ProjectID: req.ProjectID})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", WithAPIKey("key-123"))
+	bead, err := c.CreateBead(context.Background(), models.CreateBeadRequest{Title: "Fix login", ProjectID: "loom"})
+	if err != nil {
+		t.Fatalf("CreateBead failed: %v", err)
+	}
+	if bead.ID != "bd-1" || bead.Title != "Fix login" || bead.ProjectID != "loom" {
+		t.Errorf("unexpected bead: %+v", bead)
+	}
+}
+
+func TestListBeads_QueryAndPathEscaping(t *testing.T) {
+	var queries []string
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		paths = append(paths, r.URL.EscapedPath())
+		if r.URL.Path == "/api/v1/beads" {
+			json.NewEncoder(w).Encode([]models.Bead{{ID: "bd-1"}, {ID: "bd-2"}})
+			return
+		}
+		json.NewEncoder(w).Encode(models.Bead{ID: "a/b"})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	beads, err := c.ListBeads(context.Background(), &ListBeadsParams{ProjectID: "loom", Status: "open"})
+	if err != nil {
+		t.Fatalf("ListBeads failed: %v", err)
+	}
+	if len(beads) != 2 {
+		t.Errorf("expected 2 beads, got %d", len(beads))
+	}
+	if queries[0] != "project_id=loom&status=open" {
+		t.Errorf("unexpected query %q", queries[0])
+	}
+
+	if _, err := c.ListBeads(context.Background(), nil); err != nil {
+		t.Fatalf("ListBeads without params failed: %v", err)
+	}
+	if queries[1] != "" {
+		t.Errorf("expected no query, got %q", queries[1])
+	}
+
+	if _, err := c.GetBead(context.Background(), "a/b"); err != nil {
+		t.Fatalf("GetBead failed: %v", err)
+	}
+	if paths[2] != "/api/v1/beads/a%2Fb" {
+		t.Errorf("path parameter not escaped: %s", paths[2])
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(models.ErrorResponse{Error: "Bead not found"})
+	}))
+	defer srv.Close()
+
+	_, err := New(srv.URL).GetBead(context.Background(), "missing")
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *Error, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusNotFound || apiErr.Message != "Bead not found" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/auth/login" {
+			json.NewEncoder(w).Encode(map[string]string{"token": "jwt-abc"})
+			return
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer jwt-abc" {
+			t.Errorf("expected token from login, got %q", got)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	if err := c.Login(context.Background(), "admin", "secret"); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if err := c.StopAgent(context.Background(), "agent-1"); err != nil {
+		t.Fatalf("StopAgent failed: %v", err)
+	}
+}
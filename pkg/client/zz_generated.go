@@ -0,0 +1,644 @@
+// Code generated by loom-apigen from the server's route table. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/jordanhubbard/loom/pkg/models"
+	"github.com/jordanhubbard/loom/pkg/plugin"
+)
+
+// Health reports whether the API is up
+//
+// GET /api/v1/health
+func (c *Client) Health(ctx context.Context) (map[string]string, error) {
+	var out map[string]string
+	err := c.do(ctx, "GET", "/api/v1/health", nil, nil, &out)
+	return out, err
+}
+
+// DeleteOrg deletes an organization that owns no projects or users
+//
+// DELETE /api/v1/orgs/{id}
+func (c *Client) DeleteOrg(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/orgs/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListSharedLessonsParams holds the query parameters of ListSharedLessons
+type ListSharedLessonsParams struct {
+	Status string // proposed or shared; both by default
+	Limit  string // Most lessons to return, 50 by default
+}
+
+// ListSharedLessons lists the organization's shared lessons, newest first
+//
+// GET /api/v1/orgs/{id}/lessons
+func (c *Client) ListSharedLessons(ctx context.Context, id string, params *ListSharedLessonsParams) ([]models.Lesson, error) {
+	var out []models.Lesson
+	q := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			q.Set("status", params.Status)
+		}
+		if params.Limit != "" {
+			q.Set("limit", params.Limit)
+		}
+	}
+	err := c.do(ctx, "GET", "/api/v1/orgs/"+url.PathEscape(id)+"/lessons", q, nil, &out)
+	return out, err
+}
+
+// CreateSharedLesson adds a lesson to the prompts of all the organization's projects that have not opted out
+//
+// POST /api/v1/orgs/{id}/lessons
+func (c *Client) CreateSharedLesson(ctx context.Context, id string, req models.SharedLessonRequest) (*models.Lesson, error) {
+	out := new(models.Lesson)
+	if err := c.do(ctx, "POST", "/api/v1/orgs/"+url.PathEscape(id)+"/lessons", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ApproveSharedLesson approves a lesson promoted from a project, putting it into the prompts of the organization's projects
+//
+// POST /api/v1/orgs/{id}/lessons/{lesson_id}/approve
+func (c *Client) ApproveSharedLesson(ctx context.Context, id string, lessonID string) (*models.Lesson, error) {
+	out := new(models.Lesson)
+	if err := c.do(ctx, "POST", "/api/v1/orgs/"+url.PathEscape(id)+"/lessons/"+url.PathEscape(lessonID)+"/approve", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteSharedLesson rejects a promoted lesson or retires a shared one; the project lesson it came from is kept
+//
+// DELETE /api/v1/orgs/{id}/lessons/{lesson_id}
+func (c *Client) DeleteSharedLesson(ctx context.Context, id string, lessonID string) error {
+	return c.do(ctx, "DELETE", "/api/v1/orgs/"+url.PathEscape(id)+"/lessons/"+url.PathEscape(lessonID), nil, nil, nil)
+}
+
+// ListPersonas lists agent personas
+//
+// GET /api/v1/personas
+func (c *Client) ListPersonas(ctx context.Context) ([]models.Persona, error) {
+	var out []models.Persona
+	err := c.do(ctx, "GET", "/api/v1/personas", nil, nil, &out)
+	return out, err
+}
+
+// GetPersona returns a persona with its active definition
+//
+// GET /api/v1/personas/{name}
+func (c *Client) GetPersona(ctx context.Context, name string) (*models.Persona, error) {
+	out := new(models.Persona)
+	if err := c.do(ctx, "GET", "/api/v1/personas/"+url.PathEscape(name), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeletePersona removes a persona's published definition versions
+//
+// DELETE /api/v1/personas/{name}
+func (c *Client) DeletePersona(ctx context.Context, name string) error {
+	return c.do(ctx, "DELETE", "/api/v1/personas/"+url.PathEscape(name), nil, nil, nil)
+}
+
+// ListAgents lists agents
+//
+// GET /api/v1/agents
+func (c *Client) ListAgents(ctx context.Context) ([]models.Agent, error) {
+	var out []models.Agent
+	err := c.do(ctx, "GET", "/api/v1/agents", nil, nil, &out)
+	return out, err
+}
+
+// SpawnAgent spawns an agent from a persona
+//
+// POST /api/v1/agents
+func (c *Client) SpawnAgent(ctx context.Context, req models.SpawnAgentRequest) (*models.Agent, error) {
+	out := new(models.Agent)
+	if err := c.do(ctx, "POST", "/api/v1/agents", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAgent returns an agent
+//
+// GET /api/v1/agents/{id}
+func (c *Client) GetAgent(ctx context.Context, id string) (*models.Agent, error) {
+	out := new(models.Agent)
+	if err := c.do(ctx, "GET", "/api/v1/agents/"+url.PathEscape(id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StopAgent stops and removes an agent
+//
+// DELETE /api/v1/agents/{id}
+func (c *Client) StopAgent(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/agents/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListProjects lists projects
+//
+// GET /api/v1/projects
+func (c *Client) ListProjects(ctx context.Context) ([]models.Project, error) {
+	var out []models.Project
+	err := c.do(ctx, "GET", "/api/v1/projects", nil, nil, &out)
+	return out, err
+}
+
+// CreateProject creates a project
+//
+// POST /api/v1/projects
+func (c *Client) CreateProject(ctx context.Context, req models.CreateProjectRequest) (*models.Project, error) {
+	out := new(models.Project)
+	if err := c.do(ctx, "POST", "/api/v1/projects", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProject returns a project
+//
+// GET /api/v1/projects/{id}
+func (c *Client) GetProject(ctx context.Context, id string) (*models.Project, error) {
+	out := new(models.Project)
+	if err := c.do(ctx, "GET", "/api/v1/projects/"+url.PathEscape(id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateProject updates a project
+//
+// PUT /api/v1/projects/{id}
+func (c *Client) UpdateProject(ctx context.Context, id string, req models.UpdateProjectRequest) (*models.Project, error) {
+	out := new(models.Project)
+	if err := c.do(ctx, "PUT", "/api/v1/projects/"+url.PathEscape(id), nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteProject deletes a project
+//
+// DELETE /api/v1/projects/{id}
+func (c *Client) DeleteProject(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/projects/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DeleteProjectTemplate deletes a project template
+//
+// DELETE /api/v1/project-templates/{id}
+func (c *Client) DeleteProjectTemplate(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/project-templates/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ListBeadsParams holds the query parameters of ListBeads
+type ListBeadsParams struct {
+	ProjectID  string // Only beads of this project
+	Status     string // Only beads with this status
+	Type       string // Only beads of this type
+	AssignedTo string // Comma-separated agent IDs
+	Sort       string // priority, created_at, updated_at, title or status; prefix with - for descending
+	Limit      string // Page size; all matching beads when neither limit nor cursor is set
+	Cursor     string // Page to return, from the Link header of the previous page
+}
+
+// ListBeads lists beads
+//
+// GET /api/v1/beads
+func (c *Client) ListBeads(ctx context.Context, params *ListBeadsParams) ([]models.Bead, error) {
+	var out []models.Bead
+	q := url.Values{}
+	if params != nil {
+		if params.ProjectID != "" {
+			q.Set("project_id", params.ProjectID)
+		}
+		if params.Status != "" {
+			q.Set("status", params.Status)
+		}
+		if params.Type != "" {
+			q.Set("type", params.Type)
+		}
+		if params.AssignedTo != "" {
+			q.Set("assigned_to", params.AssignedTo)
+		}
+		if params.Sort != "" {
+			q.Set("sort", params.Sort)
+		}
+		if params.Limit != "" {
+			q.Set("limit", params.Limit)
+		}
+		if params.Cursor != "" {
+			q.Set("cursor", params.Cursor)
+		}
+	}
+	err := c.do(ctx, "GET", "/api/v1/beads", q, nil, &out)
+	return out, err
+}
+
+// CreateBead files a bead
+//
+// POST /api/v1/beads
+func (c *Client) CreateBead(ctx context.Context, req models.CreateBeadRequest) (*models.Bead, error) {
+	out := new(models.Bead)
+	if err := c.do(ctx, "POST", "/api/v1/beads", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetBead returns a bead
+//
+// GET /api/v1/beads/{id}
+func (c *Client) GetBead(ctx context.Context, id string) (*models.Bead, error) {
+	out := new(models.Bead)
+	if err := c.do(ctx, "GET", "/api/v1/beads/"+url.PathEscape(id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// UpdateBead updates the given fields of a bead
+//
+// PATCH /api/v1/beads/{id}
+func (c *Client) UpdateBead(ctx context.Context, id string, req models.UpdateBeadRequest) (*models.Bead, error) {
+	out := new(models.Bead)
+	if err := c.do(ctx, "PATCH", "/api/v1/beads/"+url.PathEscape(id), nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ClaimBead assigns a bead to an agent
+//
+// POST /api/v1/beads/{id}/claim
+func (c *Client) ClaimBead(ctx context.Context, id string, req models.ClaimBeadRequest) (*models.StatusResponse, error) {
+	out := new(models.StatusResponse)
+	if err := c.do(ctx, "POST", "/api/v1/beads/"+url.PathEscape(id)+"/claim", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetBeadPlan returns the execution plan an agent proposed for a bead in plan mode
+//
+// GET /api/v1/beads/{id}/plan
+func (c *Client) GetBeadPlan(ctx context.Context, id string) (*models.ExecutionPlan, error) {
+	out := new(models.ExecutionPlan)
+	if err := c.do(ctx, "GET", "/api/v1/beads/"+url.PathEscape(id)+"/plan", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ApproveBeadPlan approves a bead's proposed plan so the bead is dispatched to do the work
+//
+// POST /api/v1/beads/{id}/plan/approve
+func (c *Client) ApproveBeadPlan(ctx context.Context, id string) (*models.ExecutionPlan, error) {
+	out := new(models.ExecutionPlan)
+	if err := c.do(ctx, "POST", "/api/v1/beads/"+url.PathEscape(id)+"/plan/approve", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RejectBeadPlan rejects a bead's proposed plan so its agent plans again with the reason as feedback
+//
+// POST /api/v1/beads/{id}/plan/reject
+func (c *Client) RejectBeadPlan(ctx context.Context, id string, req models.RejectPlanRequest) (*models.ExecutionPlan, error) {
+	out := new(models.ExecutionPlan)
+	if err := c.do(ctx, "POST", "/api/v1/beads/"+url.PathEscape(id)+"/plan/reject", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListDecisionsParams holds the query parameters of ListDecisions
+type ListDecisionsParams struct {
+	Status   string // Only decisions with this status
+	Priority string // Only decisions with this priority (0-3)
+}
+
+// ListDecisions lists decisions
+//
+// GET /api/v1/decisions
+func (c *Client) ListDecisions(ctx context.Context, params *ListDecisionsParams) ([]models.DecisionBead, error) {
+	var out []models.DecisionBead
+	q := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			q.Set("status", params.Status)
+		}
+		if params.Priority != "" {
+			q.Set("priority", params.Priority)
+		}
+	}
+	err := c.do(ctx, "GET", "/api/v1/decisions", q, nil, &out)
+	return out, err
+}
+
+// GetDecision returns a decision
+//
+// GET /api/v1/decisions/{id}
+func (c *Client) GetDecision(ctx context.Context, id string) (*models.DecisionBead, error) {
+	out := new(models.DecisionBead)
+	if err := c.do(ctx, "GET", "/api/v1/decisions/"+url.PathEscape(id), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Decide resolves a decision
+//
+// POST /api/v1/decisions/{id}/decide
+func (c *Client) Decide(ctx context.Context, id string, req models.DecideRequest) (*models.StatusResponse, error) {
+	out := new(models.StatusResponse)
+	if err := c.do(ctx, "POST", "/api/v1/decisions/"+url.PathEscape(id)+"/decide", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListFileLocksParams holds the query parameters of ListFileLocks
+type ListFileLocksParams struct {
+	ProjectID string // Only locks in this project
+}
+
+// ListFileLocks lists file locks held by agents
+//
+// GET /api/v1/file-locks
+func (c *Client) ListFileLocks(ctx context.Context, params *ListFileLocksParams) ([]models.FileLock, error) {
+	var out []models.FileLock
+	q := url.Values{}
+	if params != nil {
+		if params.ProjectID != "" {
+			q.Set("project_id", params.ProjectID)
+		}
+	}
+	err := c.do(ctx, "GET", "/api/v1/file-locks", q, nil, &out)
+	return out, err
+}
+
+// LockFile locks a file for an agent; a file_path ending in / locks the directory. 409 when another agent holds it or waiting would deadlock
+//
+// POST /api/v1/file-locks
+func (c *Client) LockFile(ctx context.Context, req models.FileLockRequest) (*models.FileLock, error) {
+	out := new(models.FileLock)
+	if err := c.do(ctx, "POST", "/api/v1/file-locks", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetWorkGraphParams holds the query parameters of GetWorkGraph
+type GetWorkGraphParams struct {
+	ProjectID string // Only beads of this project
+}
+
+// GetWorkGraph returns the bead dependency graph
+//
+// GET /api/v1/work-graph
+func (c *Client) GetWorkGraph(ctx context.Context, params *GetWorkGraphParams) (*models.WorkGraph, error) {
+	out := new(models.WorkGraph)
+	q := url.Values{}
+	if params != nil {
+		if params.ProjectID != "" {
+			q.Set("project_id", params.ProjectID)
+		}
+	}
+	if err := c.do(ctx, "GET", "/api/v1/work-graph", q, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetProviderCapabilities returns what a provider's model supports, with its model tier as a custom capability, for providers whose protocol reports it
+//
+// GET /api/v1/providers/{id}/capabilities
+func (c *Client) GetProviderCapabilities(ctx context.Context, id string) (*plugin.Capabilities, error) {
+	out := new(plugin.Capabilities)
+	if err := c.do(ctx, "GET", "/api/v1/providers/"+url.PathEscape(id)+"/capabilities", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteModelPriceParams holds the query parameters of DeleteModelPrice
+type DeleteModelPriceParams struct {
+	Model      string // Model whose price to delete
+	ProviderID string // Provider the price was set for; empty for any provider
+}
+
+// DeleteModelPrice deletes a price set through the API, so the model goes back to the dataset's price
+//
+// DELETE /api/v1/models/pricing
+func (c *Client) DeleteModelPrice(ctx context.Context, params *DeleteModelPriceParams) error {
+	q := url.Values{}
+	if params != nil {
+		if params.Model != "" {
+			q.Set("model", params.Model)
+		}
+		if params.ProviderID != "" {
+			q.Set("provider_id", params.ProviderID)
+		}
+	}
+	return c.do(ctx, "DELETE", "/api/v1/models/pricing", q, nil, nil)
+}
+
+// DeleteEventWebhook deletes an event webhook subscription and its delivery log
+//
+// DELETE /api/v1/event-webhooks/{id}
+func (c *Client) DeleteEventWebhook(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/event-webhooks/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DeleteReportSchedule deletes a scheduled report and its run log
+//
+// DELETE /api/v1/report-schedules/{id}
+func (c *Client) DeleteReportSchedule(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/report-schedules/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DeleteBackup deletes a backup
+//
+// DELETE /api/v1/backups/{id}
+func (c *Client) DeleteBackup(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/backups/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DeleteGoldenPrompt deletes a golden prompt case
+//
+// DELETE /api/v1/projects/{id}/golden-prompts/{case_id}
+func (c *Client) DeleteGoldenPrompt(ctx context.Context, id string, caseID string) error {
+	return c.do(ctx, "DELETE", "/api/v1/projects/"+url.PathEscape(id)+"/golden-prompts/"+url.PathEscape(caseID), nil, nil, nil)
+}
+
+// DeleteJudge deletes a judge; its evaluations are kept
+//
+// DELETE /api/v1/projects/{id}/judges/{judge_id}
+func (c *Client) DeleteJudge(ctx context.Context, id string, judgeID string) error {
+	return c.do(ctx, "DELETE", "/api/v1/projects/"+url.PathEscape(id)+"/judges/"+url.PathEscape(judgeID), nil, nil, nil)
+}
+
+// ListSupersededLessonsParams holds the query parameters of ListSupersededLessons
+type ListSupersededLessonsParams struct {
+	Limit string // Most lessons to return, 50 by default
+}
+
+// ListSupersededLessons lists the lessons superseded by newer lessons contradicting them, most recently superseded first
+//
+// GET /api/v1/projects/{id}/lessons/superseded
+func (c *Client) ListSupersededLessons(ctx context.Context, id string, params *ListSupersededLessonsParams) ([]models.Lesson, error) {
+	var out []models.Lesson
+	q := url.Values{}
+	if params != nil {
+		if params.Limit != "" {
+			q.Set("limit", params.Limit)
+		}
+	}
+	err := c.do(ctx, "GET", "/api/v1/projects/"+url.PathEscape(id)+"/lessons/superseded", q, nil, &out)
+	return out, err
+}
+
+// GetLessonEffectivenessParams holds the query parameters of GetLessonEffectiveness
+type GetLessonEffectivenessParams struct {
+	MinInjections string // Dispatches a lesson must have gone into to be ranked, 3 by default
+	Limit         string // Most lessons listed as helpful and as useless, 10 by default
+}
+
+// GetLessonEffectiveness ranks the lessons by how much more or less often the dispatches they went into succeeded than the project's dispatches overall
+//
+// GET /api/v1/projects/{id}/lessons/effectiveness
+func (c *Client) GetLessonEffectiveness(ctx context.Context, id string, params *GetLessonEffectivenessParams) (*models.LessonEffectivenessReport, error) {
+	out := new(models.LessonEffectivenessReport)
+	q := url.Values{}
+	if params != nil {
+		if params.MinInjections != "" {
+			q.Set("min_injections", params.MinInjections)
+		}
+		if params.Limit != "" {
+			q.Set("limit", params.Limit)
+		}
+	}
+	if err := c.do(ctx, "GET", "/api/v1/projects/"+url.PathEscape(id)+"/lessons/effectiveness", q, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// EndProjectChat ends one of the caller's chat sessions
+//
+// DELETE /api/v1/projects/{id}/chat/{session_id}
+func (c *Client) EndProjectChat(ctx context.Context, id string, sessionID string) error {
+	return c.do(ctx, "DELETE", "/api/v1/projects/"+url.PathEscape(id)+"/chat/"+url.PathEscape(sessionID), nil, nil, nil)
+}
+
+// PromoteLesson proposes a lesson for the organization's shared tier, optionally reworded; it is shared once approved
+//
+// POST /api/v1/projects/{id}/lessons/{lesson_id}/promote
+func (c *Client) PromoteLesson(ctx context.Context, id string, lessonID string, req models.PromoteLessonRequest) (*models.Lesson, error) {
+	out := new(models.Lesson)
+	if err := c.do(ctx, "POST", "/api/v1/projects/"+url.PathEscape(id)+"/lessons/"+url.PathEscape(lessonID)+"/promote", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReinstateLesson puts a superseded lesson back into agents' prompts, for a contradiction that was misjudged
+//
+// POST /api/v1/projects/{id}/lessons/{lesson_id}/reinstate
+func (c *Client) ReinstateLesson(ctx context.Context, id string, lessonID string) (*models.Lesson, error) {
+	out := new(models.Lesson)
+	if err := c.do(ctx, "POST", "/api/v1/projects/"+url.PathEscape(id)+"/lessons/"+url.PathEscape(lessonID)+"/reinstate", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListPatchesParams holds the query parameters of ListPatches
+type ListPatchesParams struct {
+	Status string // Only patches in this status: pending, approved or rejected
+}
+
+// ListPatches lists the agent commits the project holds for approval as proposed patches, newest first
+//
+// GET /api/v1/projects/{id}/patches
+func (c *Client) ListPatches(ctx context.Context, id string, params *ListPatchesParams) ([]models.ProposedPatch, error) {
+	var out []models.ProposedPatch
+	q := url.Values{}
+	if params != nil {
+		if params.Status != "" {
+			q.Set("status", params.Status)
+		}
+	}
+	err := c.do(ctx, "GET", "/api/v1/projects/"+url.PathEscape(id)+"/patches", q, nil, &out)
+	return out, err
+}
+
+// ApproveLowRiskPatches approves every pending patch whose files all match the given globs, or the project's low-risk globs
+//
+// POST /api/v1/projects/{id}/patches/approve-low-risk
+func (c *Client) ApproveLowRiskPatches(ctx context.Context, id string, req models.ApproveLowRiskPatchesRequest) ([]models.ProposedPatch, error) {
+	var out []models.ProposedPatch
+	err := c.do(ctx, "POST", "/api/v1/projects/"+url.PathEscape(id)+"/patches/approve-low-risk", nil, req, &out)
+	return out, err
+}
+
+// GetPatch returns a proposed patch with its diff
+//
+// GET /api/v1/projects/{id}/patches/{patch_id}
+func (c *Client) GetPatch(ctx context.Context, id string, patchID string) (*models.ProposedPatch, error) {
+	out := new(models.ProposedPatch)
+	if err := c.do(ctx, "GET", "/api/v1/projects/"+url.PathEscape(id)+"/patches/"+url.PathEscape(patchID), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ApprovePatch approves a proposed patch so the agent's commit of it goes through
+//
+// POST /api/v1/projects/{id}/patches/{patch_id}/approve
+func (c *Client) ApprovePatch(ctx context.Context, id string, patchID string) (*models.ProposedPatch, error) {
+	out := new(models.ProposedPatch)
+	if err := c.do(ctx, "POST", "/api/v1/projects/"+url.PathEscape(id)+"/patches/"+url.PathEscape(patchID)+"/approve", nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RejectPatch rejects a proposed patch so the agent reworks its changes, told the reason
+//
+// POST /api/v1/projects/{id}/patches/{patch_id}/reject
+func (c *Client) RejectPatch(ctx context.Context, id string, patchID string, req models.RejectPatchRequest) (*models.ProposedPatch, error) {
+	out := new(models.ProposedPatch)
+	if err := c.do(ctx, "POST", "/api/v1/projects/"+url.PathEscape(id)+"/patches/"+url.PathEscape(patchID)+"/reject", nil, req, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// StopLanguageServers stops the project's language servers; the next agent request starts them again
+//
+// DELETE /api/v1/projects/{id}/language-servers
+func (c *Client) StopLanguageServers(ctx context.Context, id string) error {
+	return c.do(ctx, "DELETE", "/api/v1/projects/"+url.PathEscape(id)+"/language-servers", nil, nil, nil)
+}
+
+// GetPluginPanelData fetches a panel's data from its plugin, checked against the panel's schema; other query parameters are passed to the plugin
+//
+// GET /api/v1/plugins/{id}/panels/{panel_id}
+func (c *Client) GetPluginPanelData(ctx context.Context, id string, panelID string) (*plugin.PanelData, error) {
+	out := new(plugin.PanelData)
+	if err := c.do(ctx, "GET", "/api/v1/plugins/"+url.PathEscape(id)+"/panels/"+url.PathEscape(panelID), nil, nil, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
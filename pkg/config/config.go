@@ -29,21 +29,41 @@ type Provider struct {
 // and JSON-based configuration (for user-specific config using LoadConfig).
 type Config struct {
 	// YAML/File-based configuration fields
-	Server    ServerConfig    `yaml:"server" json:"server,omitempty"`
-	Database  DatabaseConfig  `yaml:"database" json:"database,omitempty"`
-	Beads     BeadsConfig     `yaml:"beads" json:"beads,omitempty"`
-	Agents    AgentsConfig    `yaml:"agents" json:"agents,omitempty"`
-	Security  SecurityConfig  `yaml:"security" json:"security,omitempty"`
-	Cache     CacheConfig     `yaml:"cache" json:"cache,omitempty"`
-	Readiness ReadinessConfig `yaml:"readiness" json:"readiness,omitempty"`
-	Dispatch  DispatchConfig  `yaml:"dispatch" json:"dispatch,omitempty"`
-	Git       GitConfig       `yaml:"git" json:"git,omitempty"`
-	Models    ModelsConfig    `yaml:"models" json:"models,omitempty"`
-	Projects  []ProjectConfig `yaml:"projects" json:"projects,omitempty"`
-	WebUI     WebUIConfig     `yaml:"web_ui" json:"web_ui,omitempty"`
-	Temporal  TemporalConfig  `yaml:"temporal" json:"temporal,omitempty"`
-	HotReload HotReloadConfig `yaml:"hot_reload" json:"hot_reload,omitempty"`
-	OpenClaw  OpenClawConfig  `yaml:"openclaw" json:"openclaw,omitempty"`
+	Server            ServerConfig            `yaml:"server" json:"server,omitempty"`
+	Database          DatabaseConfig          `yaml:"database" json:"database,omitempty"`
+	Beads             BeadsConfig             `yaml:"beads" json:"beads,omitempty"`
+	Agents            AgentsConfig            `yaml:"agents" json:"agents,omitempty"`
+	Security          SecurityConfig          `yaml:"security" json:"security,omitempty"`
+	Cache             CacheConfig             `yaml:"cache" json:"cache,omitempty"`
+	Readiness         ReadinessConfig         `yaml:"readiness" json:"readiness,omitempty"`
+	Dispatch          DispatchConfig          `yaml:"dispatch" json:"dispatch,omitempty"`
+	Git               GitConfig               `yaml:"git" json:"git,omitempty"`
+	Models            ModelsConfig            `yaml:"models" json:"models,omitempty"`
+	Projects          []ProjectConfig         `yaml:"projects" json:"projects,omitempty"`
+	WebUI             WebUIConfig             `yaml:"web_ui" json:"web_ui,omitempty"`
+	Temporal          TemporalConfig          `yaml:"temporal" json:"temporal,omitempty"`
+	Scheduler         SchedulerConfig         `yaml:"scheduler" json:"scheduler,omitempty"`
+	HotReload         HotReloadConfig         `yaml:"hot_reload" json:"hot_reload,omitempty"`
+	OpenClaw          OpenClawConfig          `yaml:"openclaw" json:"openclaw,omitempty"`
+	Sandbox           SandboxConfig           `yaml:"sandbox" json:"sandbox,omitempty"`
+	Logging           LoggingConfig           `yaml:"logging" json:"logging,omitempty"`
+	Plugins           PluginsConfig           `yaml:"plugins" json:"plugins,omitempty"`
+	Policy            PolicyConfig            `yaml:"policy" json:"policy,omitempty"`
+	Backup            BackupConfig            `yaml:"backup" json:"backup,omitempty"`
+	Review            ReviewConfig            `yaml:"review" json:"review,omitempty"`
+	Evaluation        EvaluationConfig        `yaml:"evaluation" json:"evaluation,omitempty"`
+	Benchmark         BenchmarkConfig         `yaml:"benchmark" json:"benchmark,omitempty"`
+	Verification      VerificationConfig      `yaml:"verification" json:"verification,omitempty"`
+	CI                CIConfig                `yaml:"ci" json:"ci,omitempty"`
+	IssueSync         IssueSyncConfig         `yaml:"issue_sync" json:"issue_sync,omitempty"`
+	Activity          ActivityConfig          `yaml:"activity" json:"activity,omitempty"`
+	Notifications     NotificationsConfig     `yaml:"notifications" json:"notifications,omitempty"`
+	Embedding         EmbeddingConfig         `yaml:"embedding" json:"embedding,omitempty"`
+	Knowledge         KnowledgeConfig         `yaml:"knowledge" json:"knowledge,omitempty"`
+	Lessons           LessonsConfig           `yaml:"lessons" json:"lessons,omitempty"`
+	Chat              ChatConfig              `yaml:"chat" json:"chat,omitempty"`
+	DependencyUpdates DependencyUpdatesConfig `yaml:"dependency_updates" json:"dependency_updates,omitempty"`
+	LanguageServers   LanguageServersConfig   `yaml:"language_servers" json:"language_servers,omitempty"`
 
 	// JSON/User-specific configuration fields
 	Providers   []Provider     `yaml:"providers,omitempty" json:"providers"`
@@ -69,6 +89,12 @@ type DatabaseConfig struct {
 	Type string `yaml:"type"` // "sqlite", "postgres"
 	Path string `yaml:"path"` // For SQLite
 	DSN  string `yaml:"dsn"`  // For Postgres
+
+	// Connection pool for Postgres; zero values take the defaults
+	MaxOpenConns    int           `yaml:"max_open_conns"`     // Default 25
+	MaxIdleConns    int           `yaml:"max_idle_conns"`     // Default 5
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`  // Default 30m
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time"` // Default 5m
 }
 
 // BeadsConfig configures beads integration
@@ -84,10 +110,10 @@ type BeadsConfig struct {
 // BeadsFederationConfig configures peer-to-peer federation via Dolt remotes
 type BeadsFederationConfig struct {
 	Enabled      bool             `yaml:"enabled"`
-	AutoSync     bool             `yaml:"auto_sync"`      // Sync with peers on startup
-	SyncInterval time.Duration    `yaml:"sync_interval"`  // Periodic sync interval (0 = disabled)
-	SyncStrategy string           `yaml:"sync_strategy"`  // "ours", "theirs", or "" (manual)
-	SyncMode     string           `yaml:"sync_mode"`      // "dolt-native" or "belt-and-suspenders"
+	AutoSync     bool             `yaml:"auto_sync"`     // Sync with peers on startup
+	SyncInterval time.Duration    `yaml:"sync_interval"` // Periodic sync interval (0 = disabled)
+	SyncStrategy string           `yaml:"sync_strategy"` // "ours", "theirs", or "" (manual)
+	SyncMode     string           `yaml:"sync_mode"`     // "dolt-native" or "belt-and-suspenders"
 	Peers        []FederationPeer `yaml:"peers"`
 }
 
@@ -116,7 +142,57 @@ type ReadinessConfig struct {
 
 // DispatchConfig controls dispatcher guardrails
 type DispatchConfig struct {
-	MaxHops int `yaml:"max_hops" json:"max_hops,omitempty"`
+	MaxHops       int                 `yaml:"max_hops" json:"max_hops,omitempty"`
+	MaxResumes    int                 `yaml:"max_resumes" json:"max_resumes,omitempty"` // Times a bead's loop may resume from a checkpoint
+	CostEstimate  CostEstimateConfig  `yaml:"cost_estimate" json:"cost_estimate,omitempty"`
+	ContextBudget ContextBudgetConfig `yaml:"context_budget" json:"context_budget,omitempty"`
+	// ResponseRepair fixes agent responses that do not parse as actions
+	ResponseRepair ResponseRepairConfig `yaml:"response_repair" json:"response_repair,omitempty"`
+	// ModelSwitching moves action loops between model tiers mid-bead
+	ModelSwitching ModelSwitchingConfig `yaml:"model_switching" json:"model_switching,omitempty"`
+}
+
+// ResponseRepairConfig controls how agent responses that do not parse are
+// rewritten to match the action schema before the agent is told it failed
+type ResponseRepairConfig struct {
+	Disabled bool   `yaml:"disabled" json:"disabled,omitempty"`
+	Provider string `yaml:"provider" json:"provider,omitempty"` // Provider ID; the cheapest active provider when empty
+}
+
+// ModelSwitchingConfig controls how an action loop changes model tier
+// mid-bead: up when the model keeps failing to produce parseable or
+// distinct actions, down while the work is only reading the project
+type ModelSwitchingConfig struct {
+	Disabled       bool `yaml:"disabled" json:"disabled,omitempty"`
+	DowngradeAfter int  `yaml:"downgrade_after" json:"downgrade_after,omitempty"` // Simple iterations in a row before moving down; default 3, negative never
+}
+
+// ContextBudgetConfig divides a model's context window between the parts of
+// the prompt a dispatch starts with. Context windows come from the provider
+// or models.metadata; section shares cap file_contents, lessons,
+// bead_history, project_facts or persona at a share of the prompt budget.
+type ContextBudgetConfig struct {
+	DefaultContextWindow int                  `yaml:"default_context_window" json:"default_context_window,omitempty"` // For models with no known window; default 32768
+	PromptShare          float64              `yaml:"prompt_share" json:"prompt_share,omitempty"`                     // Share of the window the prompt may take; default 0.6
+	SectionShares        map[string]float64   `yaml:"section_shares" json:"section_shares,omitempty"`
+	Models               []ModelContextBudget `yaml:"models" json:"models,omitempty"` // Per-model overrides
+}
+
+// ModelContextBudget overrides the prompt budget for one model
+type ModelContextBudget struct {
+	Model         string             `yaml:"model" json:"model"`
+	PromptShare   float64            `yaml:"prompt_share" json:"prompt_share,omitempty"`
+	SectionShares map[string]float64 `yaml:"section_shares" json:"section_shares,omitempty"`
+}
+
+// CostEstimateConfig sets the thresholds dispatches are held to by their
+// pre-flight cost estimates
+type CostEstimateConfig struct {
+	WarnUSD  float64 `yaml:"warn_usd" json:"warn_usd,omitempty"`   // Warn above this estimate; 0 never warns
+	BlockUSD float64 `yaml:"block_usd" json:"block_usd,omitempty"` // Block beads above this estimate until approved; 0 never blocks
+	// BudgetWarnShare warns when a dispatch would take more than this share
+	// of its organization's remaining daily budget; default 0.5
+	BudgetWarnShare float64 `yaml:"budget_warn_share" json:"budget_warn_share,omitempty"`
 }
 
 // GitConfig controls git-related settings
@@ -126,17 +202,84 @@ type GitConfig struct {
 
 // ModelsConfig configures model preferences for provider negotiation
 type ModelsConfig struct {
-	PreferredModels []PreferredModel `yaml:"preferred_models" json:"preferred_models,omitempty"`
+	PreferredModels []PreferredModel   `yaml:"preferred_models" json:"preferred_models,omitempty"`
+	Deprecations    []ModelDeprecation `yaml:"deprecations" json:"deprecations,omitempty"` // Extends the built-in deprecation catalog
+	// DeprecationWarningDays is how far ahead of end-of-life configured models are flagged
+	DeprecationWarningDays int              `yaml:"deprecation_warning_days" json:"deprecation_warning_days,omitempty"`
+	Metadata               []ModelMetadata  `yaml:"metadata" json:"metadata,omitempty"` // Overrides built-in and discovered model metadata
+	Pricing                PricingConfig    `yaml:"pricing" json:"pricing,omitempty"`
+	Scoring                ScoringConfig    `yaml:"scoring" json:"scoring,omitempty"`
+	Queue                  QueueConfig      `yaml:"queue" json:"queue,omitempty"`
+	Saturation             SaturationConfig `yaml:"saturation" json:"saturation,omitempty"`
+}
+
+// SaturationConfig sets when a self-hosted vLLM or TGI server counts as
+// saturated, from the queue depth and KV cache usage heartbeats read from
+// its metrics. The dispatcher routes new work around saturated servers and
+// publishes a provider.saturated activity with a scale-up hint.
+type SaturationConfig struct {
+	MaxWaiting    int     `yaml:"max_waiting" json:"max_waiting,omitempty"`         // Requests queued on the server; default 8
+	MaxCacheUsage float64 `yaml:"max_cache_usage" json:"max_cache_usage,omitempty"` // Share of the KV cache in use, 0-1; default 0.95
+}
+
+// QueueConfig limits how many requests each provider serves at once.
+// Requests beyond the limit wait in priority lanes: interactive chat first,
+// then agent work by bead priority, then background jobs such as
+// benchmarks. When a queue is full, the lowest priority request is shed.
+type QueueConfig struct {
+	MaxConcurrent int            `yaml:"max_concurrent" json:"max_concurrent,omitempty"` // Per provider; 0 leaves providers unlimited
+	MaxDepth      int            `yaml:"max_depth" json:"max_depth,omitempty"`           // Requests that may wait per provider; default 100
+	Providers     map[string]int `yaml:"providers" json:"providers,omitempty"`           // max_concurrent by provider ID
+}
+
+// ScoringConfig configures how the provider scorer's metrics are kept.
+// They are saved to the database periodically and at shutdown and
+// reloaded on startup, so ranking survives restarts.
+type ScoringConfig struct {
+	HalfLife         time.Duration `yaml:"half_life" json:"half_life,omitempty"`                 // Age at which a request outcome counts half toward the error rate; default 24h
+	PersistInterval  time.Duration `yaml:"persist_interval" json:"persist_interval,omitempty"`   // Between saves and history snapshots; default 5m
+	HistoryRetention time.Duration `yaml:"history_retention" json:"history_retention,omitempty"` // Snapshots older than this are deleted; default 720h
+}
+
+// PricingConfig configures the pricing catalog requests are costed from.
+// Prices in Metadata override the dataset's.
+type PricingConfig struct {
+	RefreshInterval time.Duration `yaml:"refresh_interval" json:"refresh_interval,omitempty"` // Default 24h
+	DatasetPath     string        `yaml:"dataset_path" json:"dataset_path,omitempty"`         // Replaces the bundled price list
+}
+
+// ModelMetadata describes a model's limits, capabilities and pricing.
+// Omitted fields fall back to discovered or built-in values.
+type ModelMetadata struct {
+	Model               string  `yaml:"model" json:"model"`
+	ContextWindow       int     `yaml:"context_window" json:"context_window,omitempty"`
+	MaxOutputTokens     int     `yaml:"max_output_tokens" json:"max_output_tokens,omitempty"`
+	SupportsTools       *bool   `yaml:"supports_tools" json:"supports_tools,omitempty"`
+	SupportsVision      *bool   `yaml:"supports_vision" json:"supports_vision,omitempty"`
+	SupportsJSONMode    *bool   `yaml:"supports_json_mode" json:"supports_json_mode,omitempty"`
+	SupportsJSONSchema  *bool   `yaml:"supports_json_schema" json:"supports_json_schema,omitempty"`
+	InputCostPerMToken  float64 `yaml:"input_cost_per_mtoken" json:"input_cost_per_mtoken,omitempty"`
+	OutputCostPerMToken float64 `yaml:"output_cost_per_mtoken" json:"output_cost_per_mtoken,omitempty"`
+	KnowledgeCutoff     string  `yaml:"knowledge_cutoff" json:"knowledge_cutoff,omitempty"` // YYYY-MM
+}
+
+// ModelDeprecation describes a model's end-of-life schedule (dates are YYYY-MM-DD)
+type ModelDeprecation struct {
+	Model        string `yaml:"model" json:"model"`
+	DeprecatedOn string `yaml:"deprecated_on" json:"deprecated_on,omitempty"`
+	EndOfLife    string `yaml:"end_of_life" json:"end_of_life"`
+	Replacement  string `yaml:"replacement" json:"replacement,omitempty"`
+	Notes        string `yaml:"notes" json:"notes,omitempty"`
 }
 
 // PreferredModel represents a model preference for negotiation with providers.
 // When a provider returns multiple models, Loom selects the best match from this list.
 type PreferredModel struct {
-	Name      string `yaml:"name" json:"name"`                               // Full model name (e.g., "Qwen/Qwen2.5-Coder-32B-Instruct")
-	Rank      int    `yaml:"rank" json:"rank"`                               // Priority rank (1 = most preferred)
-	Tier      string `yaml:"tier" json:"tier,omitempty"`                     // Complexity tier: "extended", "complex", "medium", "simple"
-	MinVRAMGB int    `yaml:"min_vram_gb" json:"min_vram_gb,omitempty"`       // Minimum VRAM required (0 = cloud/unknown)
-	Notes     string `yaml:"notes" json:"notes,omitempty"`                   // Human-readable notes about the model
+	Name      string `yaml:"name" json:"name"`                         // Full model name (e.g., "Qwen/Qwen2.5-Coder-32B-Instruct")
+	Rank      int    `yaml:"rank" json:"rank"`                         // Priority rank (1 = most preferred)
+	Tier      string `yaml:"tier" json:"tier,omitempty"`               // Complexity tier: "extended", "complex", "medium", "simple"
+	MinVRAMGB int    `yaml:"min_vram_gb" json:"min_vram_gb,omitempty"` // Minimum VRAM required (0 = cloud/unknown)
+	Notes     string `yaml:"notes" json:"notes,omitempty"`             // Human-readable notes about the model
 }
 
 // SecurityConfig configures authentication and authorization
@@ -149,6 +292,56 @@ type SecurityConfig struct {
 	APIKeys        []string `yaml:"api_keys,omitempty"`
 	JWTSecret      string   `yaml:"jwt_secret" json:"jwt_secret,omitempty"`
 	WebhookSecret  string   `yaml:"webhook_secret" json:"webhook_secret,omitempty"` // GitHub webhook secret
+
+	// AuditLogPath is the JSONL file security audit events are appended to
+	AuditLogPath string `yaml:"audit_log_path" json:"audit_log_path,omitempty"`
+	// KeyRotationGracePeriod is how long a replaced provider key is kept
+	// (and can be rolled back to) before it is retired
+	KeyRotationGracePeriod time.Duration `yaml:"key_rotation_grace_period" json:"key_rotation_grace_period,omitempty"`
+	// OIDC enables single sign-on through an OpenID Connect provider
+	OIDC OIDCConfig `yaml:"oidc" json:"oidc,omitempty"`
+	// KeyStore chooses what protects the key store's data key
+	KeyStore KeyStoreConfig `yaml:"key_store" json:"key_store,omitempty"`
+}
+
+// KeyStoreConfig chooses the backend that encrypts the key store's data key
+// (envelope encryption). The master password protects it by default; a KMS
+// backend keeps the key that wraps it outside Loom. Credentials for the
+// backend come from its usual environment variables, never this file.
+type KeyStoreConfig struct {
+	Backend string `yaml:"backend" json:"backend,omitempty"` // password (default), vault, awskms or gcpkms
+	// KeyID names the key encryption key: a Vault transit key, an AWS KMS
+	// key ARN or alias, or a GCP KMS key's resource name
+	KeyID   string `yaml:"key_id" json:"key_id,omitempty"`
+	Address string `yaml:"address" json:"address,omitempty"` // Vault server (default VAULT_ADDR), or another API endpoint for AWS or GCP
+	Mount   string `yaml:"mount" json:"mount,omitempty"`     // Vault transit mount (default "transit")
+	Region  string `yaml:"region" json:"region,omitempty"`   // AWS region (default from the key ARN or AWS_REGION)
+}
+
+// OIDCConfig configures OpenID Connect login (Okta, Google, Entra ID, ...).
+// Users are provisioned on their first login with a role mapped from their
+// groups, so no local passwords are needed.
+type OIDCConfig struct {
+	Enabled      bool     `yaml:"enabled" json:"enabled"`
+	ProviderName string   `yaml:"provider_name" json:"provider_name,omitempty"` // Shown on the login button
+	IssuerURL    string   `yaml:"issuer_url" json:"issuer_url"`                 // Discovery is read from <issuer>/.well-known/openid-configuration
+	ClientID     string   `yaml:"client_id" json:"client_id"`
+	ClientSecret string   `yaml:"client_secret" json:"-"`
+	RedirectURL  string   `yaml:"redirect_url" json:"redirect_url"` // Must point at /api/v1/auth/oidc/callback
+	Scopes       []string `yaml:"scopes" json:"scopes,omitempty"`   // Default: openid, email, profile
+
+	// GroupsClaim is the ID token claim listing the user's groups (default
+	// "groups"; use "roles" for Entra ID app roles)
+	GroupsClaim string `yaml:"groups_claim" json:"groups_claim,omitempty"`
+	// GroupRoles maps IdP groups to Loom roles; the most privileged wins
+	GroupRoles map[string]string `yaml:"group_roles" json:"group_roles,omitempty"`
+	// DefaultRole is given to users in no mapped group. Empty denies them.
+	DefaultRole string `yaml:"default_role" json:"default_role,omitempty"`
+	// AllowedDomains restricts login to these email domains
+	AllowedDomains []string `yaml:"allowed_domains" json:"allowed_domains,omitempty"`
+	// PostLoginRedirect is where the browser is sent with its token
+	// (default "/")
+	PostLoginRedirect string `yaml:"post_login_redirect" json:"post_login_redirect,omitempty"`
 }
 
 // TemporalConfig configures Temporal workflow engine
@@ -160,32 +353,423 @@ type TemporalConfig struct {
 	WorkflowTaskTimeout      time.Duration `yaml:"workflow_task_timeout"`
 	EnableEventBus           bool          `yaml:"enable_event_bus"`
 	EventBufferSize          int           `yaml:"event_buffer_size"`
+
+	// Activities overrides the timeouts and retries of activities by name,
+	// such as LoomHeartbeatActivity. The embedded scheduler applies them too.
+	Activities map[string]ActivityPolicy `yaml:"activities" json:"activities,omitempty"`
+}
+
+// ActivityPolicy bounds each attempt of an activity and how it is retried.
+// Zero fields keep the activity's default.
+type ActivityPolicy struct {
+	StartToCloseTimeout time.Duration `yaml:"start_to_close_timeout" json:"start_to_close_timeout,omitempty"` // Per attempt
+	HeartbeatTimeout    time.Duration `yaml:"heartbeat_timeout" json:"heartbeat_timeout,omitempty"`           // Temporal only: fail an attempt whose worker stops heartbeating
+	MaximumAttempts     int           `yaml:"maximum_attempts" json:"maximum_attempts,omitempty"`
+	InitialInterval     time.Duration `yaml:"initial_interval" json:"initial_interval,omitempty"` // Delay before the first retry
+	BackoffCoefficient  float64       `yaml:"backoff_coefficient" json:"backoff_coefficient,omitempty"`
+	MaximumInterval     time.Duration `yaml:"maximum_interval" json:"maximum_interval,omitempty"`
+	NonRetryableErrors  []string      `yaml:"non_retryable_errors" json:"non_retryable_errors,omitempty"` // Temporal only: error types never retried
+}
+
+// defaultActivityPolicies are the activities the workflows run, with the
+// bounds they run under unless configured otherwise. The Ralph Loop and
+// REPL queries heartbeat, so a worker that dies mid-attempt is noticed in
+// well under their start-to-close timeouts.
+var defaultActivityPolicies = map[string]ActivityPolicy{
+	"LoomHeartbeatActivity":     {StartToCloseTimeout: 10 * time.Minute, HeartbeatTimeout: 2 * time.Minute, MaximumAttempts: 1},
+	"DispatchOnceActivity":      {StartToCloseTimeout: 30 * time.Minute, MaximumAttempts: 3},
+	"ProviderHeartbeatActivity": {StartToCloseTimeout: 2 * time.Minute, MaximumAttempts: 1},
+	"ProviderQueryActivity":     {StartToCloseTimeout: 3 * time.Minute, HeartbeatTimeout: 30 * time.Second, MaximumAttempts: 2},
+}
+
+// ActivityPolicy returns the policy the named activity runs under: its
+// default, with the configured fields laid over it
+func (c *TemporalConfig) ActivityPolicy(name string) ActivityPolicy {
+	p := defaultActivityPolicies[name]
+	o, ok := c.Activities[name]
+	if !ok {
+		return p
+	}
+	if o.StartToCloseTimeout > 0 {
+		p.StartToCloseTimeout = o.StartToCloseTimeout
+	}
+	if o.HeartbeatTimeout > 0 {
+		p.HeartbeatTimeout = o.HeartbeatTimeout
+	}
+	if o.MaximumAttempts > 0 {
+		p.MaximumAttempts = o.MaximumAttempts
+	}
+	if o.InitialInterval > 0 {
+		p.InitialInterval = o.InitialInterval
+	}
+	if o.BackoffCoefficient > 0 {
+		p.BackoffCoefficient = o.BackoffCoefficient
+	}
+	if o.MaximumInterval > 0 {
+		p.MaximumInterval = o.MaximumInterval
+	}
+	if len(o.NonRetryableErrors) > 0 {
+		p.NonRetryableErrors = o.NonRetryableErrors
+	}
+	return p
+}
+
+// SchedulerConfig chooses what runs the heartbeat and dispatch workflows.
+// The embedded scheduler needs no Temporal cluster: its timers are kept in
+// the database, and instances sharing the database take turns firing them.
+type SchedulerConfig struct {
+	Backend      string        `yaml:"backend" json:"backend,omitempty"`             // "temporal" (default) or "embedded"
+	PollInterval time.Duration `yaml:"poll_interval" json:"poll_interval,omitempty"` // How often the embedded scheduler looks for due timers; default 1s
 }
 
 // CacheConfig configures response caching
 type CacheConfig struct {
-	Enabled       bool          `yaml:"enabled" json:"enabled"`
-	Backend       string        `yaml:"backend" json:"backend"` // "memory" or "redis"
-	DefaultTTL    time.Duration `yaml:"default_ttl" json:"default_ttl"`
-	MaxSize       int           `yaml:"max_size" json:"max_size"`
-	MaxMemoryMB   int           `yaml:"max_memory_mb" json:"max_memory_mb"`
-	CleanupPeriod time.Duration `yaml:"cleanup_period" json:"cleanup_period"`
-	RedisURL      string        `yaml:"redis_url" json:"redis_url,omitempty"` // Redis connection URL
+	Enabled          bool            `yaml:"enabled" json:"enabled"`
+	Backend          string          `yaml:"backend" json:"backend"` // "memory" or "redis"
+	DefaultTTL       time.Duration   `yaml:"default_ttl" json:"default_ttl"`
+	MaxSize          int             `yaml:"max_size" json:"max_size"`
+	MaxMemoryMB      int             `yaml:"max_memory_mb" json:"max_memory_mb"`
+	CleanupPeriod    time.Duration   `yaml:"cleanup_period" json:"cleanup_period"`
+	RedisURL         string          `yaml:"redis_url" json:"redis_url,omitempty"`                   // Redis connection URL
+	Compression      string          `yaml:"compression" json:"compression,omitempty"`               // "gzip" or "zstd" for Redis entries; empty stores plain JSON
+	CompressMinBytes int             `yaml:"compress_min_bytes" json:"compress_min_bytes,omitempty"` // Smaller entries are not compressed; default 1024
+	Keys             CacheKeyConfig  `yaml:"keys" json:"keys,omitempty"`
+	Warm             CacheWarmConfig `yaml:"warm" json:"warm,omitempty"`
+}
+
+// CacheKeyConfig canonicalizes requests before hashing them into cache keys,
+// so requests differing only in field order, whitespace or caller tags hit
+// the same entry
+type CacheKeyConfig struct {
+	Canonicalize           bool     `yaml:"canonicalize" json:"canonicalize"`
+	StripFields            []string `yaml:"strip_fields" json:"strip_fields,omitempty"`                         // Top-level fields ignored; default request_id, user, user_id, tags, metadata
+	IgnoreTemperatureBelow float64  `yaml:"ignore_temperature_below" json:"ignore_temperature_below,omitempty"` // Temperatures under this are left out of keys; 0 keeps them
+}
+
+// CacheWarmConfig pre-populates the cache from the prompts that repeat most
+// (or cost most) in recent analytics logs, at startup and on a schedule.
+// Only requests logged with response bodies can be warmed.
+type CacheWarmConfig struct {
+	Enabled        bool          `yaml:"enabled" json:"enabled"`
+	Strategy       string        `yaml:"strategy" json:"strategy,omitempty"`               // "frequency" (default) or "cost"
+	TimeWindow     time.Duration `yaml:"time_window" json:"time_window,omitempty"`         // How far back to scan; default 24h
+	MaxEntries     int           `yaml:"max_entries" json:"max_entries,omitempty"`         // Default 100
+	MinOccurrences int           `yaml:"min_occurrences" json:"min_occurrences,omitempty"` // Default 2
+	TTL            time.Duration `yaml:"ttl" json:"ttl,omitempty"`                         // Default: the cache's default_ttl
+	Interval       time.Duration `yaml:"interval" json:"interval,omitempty"`               // Re-warm period; 0 warms only at startup
 }
 
 // ProjectConfig represents a project configuration
 type ProjectConfig struct {
-	ID              string            `yaml:"id"`
-	Name            string            `yaml:"name"`
-	GitRepo         string            `yaml:"git_repo"`
-	Branch          string            `yaml:"branch"`
-	BeadsPath       string            `yaml:"beads_path"`
-	GitAuthMethod   string            `yaml:"git_auth_method" json:"git_auth_method,omitempty"`
-	GitStrategy     string            `yaml:"git_strategy" json:"git_strategy,omitempty"`
-	GitCredentialID string            `yaml:"git_credential_id" json:"git_credential_id,omitempty"`
-	IsPerpetual     bool              `yaml:"is_perpetual" json:"is_perpetual,omitempty"`
-	IsSticky        bool              `yaml:"is_sticky" json:"is_sticky,omitempty"`
-	Context         map[string]string `yaml:"context"`
+	ID              string              `yaml:"id"`
+	Name            string              `yaml:"name"`
+	GitRepo         string              `yaml:"git_repo"`
+	Branch          string              `yaml:"branch"`
+	BeadsPath       string              `yaml:"beads_path"`
+	GitAuthMethod   string              `yaml:"git_auth_method" json:"git_auth_method,omitempty"`
+	GitStrategy     string              `yaml:"git_strategy" json:"git_strategy,omitempty"`
+	GitCredentialID string              `yaml:"git_credential_id" json:"git_credential_id,omitempty"`
+	IsPerpetual     bool                `yaml:"is_perpetual" json:"is_perpetual,omitempty"`
+	IsSticky        bool                `yaml:"is_sticky" json:"is_sticky,omitempty"`
+	Context         map[string]string   `yaml:"context"`
+	Sandbox         *SandboxConfig      `yaml:"sandbox,omitempty" json:"sandbox,omitempty"`                 // Overrides the global sandbox for this project
+	Verification    *VerificationConfig `yaml:"verification,omitempty" json:"verification,omitempty"`       // Replaces the global verification for this project
+	RequiredChecks  []string            `yaml:"required_checks,omitempty" json:"required_checks,omitempty"` // Replaces the global ci.required_checks for this project
+	IssueTracker    *IssueTrackerConfig `yaml:"issue_tracker,omitempty" json:"issue_tracker,omitempty"`     // Syncs the project's beads with GitHub Issues or Jira
+}
+
+// SandboxConfig controls where agent commands run. In docker/podman mode
+// each command runs in a fresh container with the project worktree mounted.
+type SandboxConfig struct {
+	Mode          string            `yaml:"mode" json:"mode,omitempty"` // local, docker or podman
+	Image         string            `yaml:"image" json:"image,omitempty"`
+	CPUs          string            `yaml:"cpus" json:"cpus,omitempty"`
+	Memory        string            `yaml:"memory" json:"memory,omitempty"`
+	Network       string            `yaml:"network" json:"network,omitempty"` // none, bridge or host
+	MountPath     string            `yaml:"mount_path" json:"mount_path,omitempty"`
+	ReadOnly      bool              `yaml:"read_only" json:"read_only,omitempty"`
+	Env           map[string]string `yaml:"env" json:"env,omitempty"`
+	FallbackLocal bool              `yaml:"fallback_local" json:"fallback_local,omitempty"` // Run on the host if the runtime is missing
+}
+
+// LoggingConfig configures the structured log. Modules overrides the level
+// of individual modules, such as dispatch, provider or git; admins can
+// change levels at runtime through /api/v1/logs/levels.
+type LoggingConfig struct {
+	Level   string            `yaml:"level" json:"level,omitempty"`   // debug, info, warn or error
+	Format  string            `yaml:"format" json:"format,omitempty"` // json or text
+	Modules map[string]string `yaml:"modules" json:"modules,omitempty"`
+}
+
+// PluginsConfig configures provider and dashboard panel plugins. Plugins
+// are discovered from plugin.yaml manifests under Dir; an empty Dir
+// disables plugin loading.
+type PluginsConfig struct {
+	Dir string `yaml:"dir" json:"dir,omitempty"`
+}
+
+// PolicyConfig configures the arbiter's policy engine. File is a YAML or
+// JSON policy document of escalation, approval, budget and tool-permission
+// rules that applies to every project; projects publish their own versioned
+// policies on top of it through /api/v1/projects/{id}/policy.
+type PolicyConfig struct {
+	File string `yaml:"file" json:"file,omitempty"`
+}
+
+// BackupConfig configures backups of the database and key store. On-demand
+// backups are always available; scheduled ones are taken every Interval.
+type BackupConfig struct {
+	Dir      string        `yaml:"dir" json:"dir,omitempty"`           // Default ./backups
+	Interval time.Duration `yaml:"interval" json:"interval,omitempty"` // 0 takes no scheduled backups
+	Keep     int           `yaml:"keep" json:"keep,omitempty"`         // Newest backups kept; default 14
+	MaxAge   time.Duration `yaml:"max_age" json:"max_age,omitempty"`   // 0 removes none by age
+}
+
+// ReviewConfig configures the automated review pull requests opened by
+// agents go through before human review. The reviewer persona's findings
+// are posted on the pull request; with AutoMerge, pull requests without
+// blocking findings are merged.
+type ReviewConfig struct {
+	Enabled      bool          `yaml:"enabled" json:"enabled,omitempty"`
+	Persona      string        `yaml:"persona" json:"persona,omitempty"`               // Default default/code-reviewer
+	ProviderID   string        `yaml:"provider_id" json:"provider_id,omitempty"`       // Empty picks a provider by the persona's model tier
+	AutoMerge    bool          `yaml:"auto_merge" json:"auto_merge,omitempty"`         // Merge pull requests whose review is clean
+	MergeMethod  string        `yaml:"merge_method" json:"merge_method,omitempty"`     // merge, squash or rebase; default squash
+	MaxDiffBytes int           `yaml:"max_diff_bytes" json:"max_diff_bytes,omitempty"` // Default 200000
+	Timeout      time.Duration `yaml:"timeout" json:"timeout,omitempty"`               // Per review; default 5m
+}
+
+// EvaluationConfig configures the judges that grade what dispatches
+// produce. Each project's judges, or a default judge when it has none,
+// score the bead's instructions, the agent's answer and its diff, and the
+// scores rank providers by the quality of their work.
+type EvaluationConfig struct {
+	Enabled      bool          `yaml:"enabled" json:"enabled,omitempty"`
+	Persona      string        `yaml:"persona" json:"persona,omitempty"`               // Default judge persona; default default/code-reviewer
+	ProviderID   string        `yaml:"provider_id" json:"provider_id,omitempty"`       // Empty picks a provider by the persona's model tier
+	MaxDiffBytes int           `yaml:"max_diff_bytes" json:"max_diff_bytes,omitempty"` // Default 100000
+	Timeout      time.Duration `yaml:"timeout" json:"timeout,omitempty"`               // Per evaluation; default 2m
+	Window       time.Duration `yaml:"window" json:"window,omitempty"`                 // Evaluations counted toward quality; default 336h
+	MinSamples   int           `yaml:"min_samples" json:"min_samples,omitempty"`       // Evaluations before quality affects routing; default 3
+}
+
+// BenchmarkConfig configures the provider benchmark on golden tasks. Runs
+// happen on demand through the API and, with an interval, on a schedule;
+// their measured latency and cost feed the provider scorer.
+type BenchmarkConfig struct {
+	Interval    time.Duration `yaml:"interval" json:"interval,omitempty"`         // Between scheduled runs; 0 runs only on demand
+	Timeout     time.Duration `yaml:"timeout" json:"timeout,omitempty"`           // Per task; default 2m
+	ProviderIDs []string      `yaml:"provider_ids" json:"provider_ids,omitempty"` // Providers scheduled runs cover; empty covers every active provider
+}
+
+// CIConfig configures how CI checks on bead branches are tracked. Results
+// arrive by webhook or by polling the git host; pull requests are not
+// opened or merged until the required checks pass.
+type CIConfig struct {
+	Enabled         bool          `yaml:"enabled" json:"enabled,omitempty"`
+	RequiredChecks  []string      `yaml:"required_checks" json:"required_checks,omitempty"`   // Empty requires every reported check
+	PollInterval    time.Duration `yaml:"poll_interval" json:"poll_interval,omitempty"`       // 0 relies on webhooks
+	LessonThreshold int           `yaml:"lesson_threshold" json:"lesson_threshold,omitempty"` // Failed commits in a row that become a lesson; default 2
+}
+
+// IssueSyncConfig configures the two-way sync of beads with issue
+// trackers. Projects opt in with their issue_tracker setting; issues are
+// imported as beads, and bead status changes and comments go back.
+type IssueSyncConfig struct {
+	Enabled     bool           `yaml:"enabled" json:"enabled,omitempty"`
+	Interval    time.Duration  `yaml:"interval" json:"interval,omitempty"`         // How often trackers are polled; 0 relies on webhooks
+	PriorityMap map[string]int `yaml:"priority_map" json:"priority_map,omitempty"` // Tracker priorities and labels to bead priorities, over the defaults
+	Jira        JiraConfig     `yaml:"jira" json:"jira,omitempty"`
+}
+
+// ActivityConfig configures the activity feed
+type ActivityConfig struct {
+	Retention   ActivityRetentionConfig            `yaml:"retention" json:"retention,omitempty"`
+	Aggregation map[string]ActivityAggregationRule `yaml:"aggregation" json:"aggregation,omitempty"` // By event type, replacing the built-in rule for it
+}
+
+// ActivityAggregationRule folds bursts of an event type into one activity
+// feed entry: an event joins the latest entry of its group when it comes
+// within Window of that entry's latest event, so the window slides.
+type ActivityAggregationRule struct {
+	Window    time.Duration `yaml:"window" json:"window,omitempty"`         // 0 turns aggregation off for the event type
+	GroupBy   []string      `yaml:"group_by" json:"group_by,omitempty"`     // project, actor, agent, bead or resource; default project and actor
+	MaxEvents int           `yaml:"max_events" json:"max_events,omitempty"` // Events an entry stands for before the next starts; 0 for no limit
+}
+
+// ActivityRetentionConfig configures how long activity feed events are
+// kept. Expired events are exported as JSONL to the archive directory and
+// S3 bucket, when set, then compacted into rollups that are kept longer.
+type ActivityRetentionConfig struct {
+	Enabled         bool               `yaml:"enabled" json:"enabled,omitempty"`
+	RawRetention    time.Duration      `yaml:"raw_retention" json:"raw_retention,omitempty"`       // Default 720h (30 days)
+	RollupRetention time.Duration      `yaml:"rollup_retention" json:"rollup_retention,omitempty"` // Default 8760h (a year)
+	Interval        time.Duration      `yaml:"interval" json:"interval,omitempty"`                 // Time between compaction runs; default 1h
+	BatchSize       int                `yaml:"batch_size" json:"batch_size,omitempty"`             // Events compacted per transaction; default 1000
+	ArchiveDir      string             `yaml:"archive_dir" json:"archive_dir,omitempty"`           // Directory expired events are exported to
+	ArchiveS3       *ActivityArchiveS3 `yaml:"archive_s3,omitempty" json:"archive_s3,omitempty"`   // Bucket expired events are exported to; needs AWS credentials
+}
+
+// ActivityArchiveS3 is the S3 or S3-compatible bucket expired activity
+// feed events are exported to
+type ActivityArchiveS3 struct {
+	Bucket   string `yaml:"bucket" json:"bucket"`
+	Prefix   string `yaml:"prefix" json:"prefix,omitempty"`
+	Region   string `yaml:"region" json:"region,omitempty"`     // Default AWS_REGION or us-east-1
+	Endpoint string `yaml:"endpoint" json:"endpoint,omitempty"` // S3-compatible endpoint; path-style addressing is used when set
+}
+
+// NotificationsConfig configures how notifications leave Loom
+type NotificationsConfig struct {
+	Push       PushConfig       `yaml:"push" json:"push,omitempty"`
+	Escalation EscalationConfig `yaml:"escalation" json:"escalation,omitempty"`
+}
+
+// EscalationConfig configures escalation of unacknowledged notifications.
+// High and critical notifications in a project with an on-call chain wait
+// AckWindow for acknowledgment, then go to the next person in the chain;
+// once the chain is exhausted a decision is put to the CEO.
+type EscalationConfig struct {
+	AckWindow time.Duration `yaml:"ack_window" json:"ack_window,omitempty"` // Default 30m; a project's chain can set its own
+	Disabled  bool          `yaml:"disabled" json:"disabled,omitempty"`
+}
+
+// PushConfig configures mobile push notifications. Users register device
+// tokens and opt in with their enable_push preference; notifications reach
+// Android devices through FCM and iOS devices through APNs, for each
+// service configured here.
+type PushConfig struct {
+	BaseURL string      `yaml:"base_url" json:"base_url,omitempty"` // Public URL of the web UI notification links are made absolute against
+	FCM     *FCMConfig  `yaml:"fcm,omitempty" json:"fcm,omitempty"`
+	APNs    *APNsConfig `yaml:"apns,omitempty" json:"apns,omitempty"`
+}
+
+// Enabled reports whether a push service is configured
+func (p PushConfig) Enabled() bool {
+	return p.FCM != nil || p.APNs != nil
+}
+
+// FCMConfig is the Firebase project push notifications are sent through
+type FCMConfig struct {
+	CredentialsFile string `yaml:"credentials_file" json:"credentials_file"` // Service account key file with the Firebase Messaging role
+	ProjectID       string `yaml:"project_id" json:"project_id,omitempty"`   // Default the service account's project
+}
+
+// APNsConfig is the Apple Push Notification service key and app push
+// notifications are sent with
+type APNsConfig struct {
+	KeyFile string `yaml:"key_file" json:"key_file"`         // .p8 token signing key
+	KeyID   string `yaml:"key_id" json:"key_id"`             // ID of the signing key
+	TeamID  string `yaml:"team_id" json:"team_id"`           // Apple developer team ID
+	Topic   string `yaml:"topic" json:"topic"`               // The app's bundle ID
+	Sandbox bool   `yaml:"sandbox" json:"sandbox,omitempty"` // Send to the development environment
+}
+
+// EmbeddingConfig configures the embedder lessons are searched with.
+// Without a model the built-in hash embedder is used. When the model
+// changes, stored lessons are re-embedded in the background, and until they
+// all are, searches embed queries with the previous model too. Model
+// embeddings are cached by content hash, so a text is embedded only once.
+type EmbeddingConfig struct {
+	Endpoint        string        `yaml:"endpoint" json:"endpoint,omitempty"`                 // OpenAI-compatible base URL serving /v1/embeddings
+	APIKey          string        `yaml:"api_key" json:"api_key,omitempty"`                   // Usually a secret: reference
+	Model           string        `yaml:"model" json:"model,omitempty"`                       // Empty uses the hash embedder
+	PreviousModel   string        `yaml:"previous_model" json:"previous_model,omitempty"`     // Model at the same endpoint lessons were embedded with before
+	ReembedRate     float64       `yaml:"reembed_rate" json:"reembed_rate,omitempty"`         // Lessons re-embedded per second; default 2
+	ReembedBatch    int           `yaml:"reembed_batch" json:"reembed_batch,omitempty"`       // Lessons per embedding request; default 16
+	ReembedInterval time.Duration `yaml:"reembed_interval" json:"reembed_interval,omitempty"` // Time between checks for lessons to re-embed; default 10m
+	CacheSize       int           `yaml:"cache_size" json:"cache_size,omitempty"`             // Model embeddings kept in memory; default 4096
+	CacheTTL        time.Duration `yaml:"cache_ttl" json:"cache_ttl,omitempty"`               // Age at which embeddings cached in the database are deleted; default 720h
+}
+
+// KnowledgeConfig configures the knowledge graph linking lessons to the
+// beads, agents, commits and files they came from
+type KnowledgeConfig struct {
+	IndexInterval time.Duration `yaml:"index_interval" json:"index_interval,omitempty"` // Time between reads of the projects' git history; default 15m
+	MaxCommits    int           `yaml:"max_commits" json:"max_commits,omitempty"`       // Most recent bead commits read per project; default 200
+}
+
+// LessonsConfig configures how contradicting lessons are found. A new
+// lesson similar enough to an existing one is put to a model, which
+// decides whether they contradict and which to keep; the other is marked
+// superseded and no longer injected into prompts.
+type LessonsConfig struct {
+	ContradictionSimilarity float64 `yaml:"contradiction_similarity" json:"contradiction_similarity,omitempty"` // Embedding similarity at which two lessons are compared; default 0.8
+	AdjudicatorProvider     string  `yaml:"adjudicator_provider" json:"adjudicator_provider,omitempty"`         // Provider whose model decides; empty uses the first active provider, "none" a keyword heuristic
+
+	Search LessonSearchConfig `yaml:"search" json:"search,omitempty"`
+	Index  LessonIndexConfig  `yaml:"index" json:"index,omitempty"`
+}
+
+// LessonSearchConfig bounds the cost of finding a task's lessons by
+// similarity. Lessons are read from the database a page at a time and
+// compared in batches, newest first.
+type LessonSearchConfig struct {
+	PageSize      int     `yaml:"page_size" json:"page_size,omitempty"`           // Lessons read at a time; default 500
+	MaxCandidates int     `yaml:"max_candidates" json:"max_candidates,omitempty"` // Newest lessons compared per search; 0 compares them all
+	ScanRate      float64 `yaml:"scan_rate" json:"scan_rate,omitempty"`           // Lessons one search reads per second; 0 is unlimited
+	MaxScans      int     `yaml:"max_scans" json:"max_scans,omitempty"`           // Searches reading lessons at once; default 4
+}
+
+// LessonIndexConfig configures the in-memory HNSW index projects with many
+// lessons are searched through instead of comparing every lesson. It is
+// off unless min_lessons is set.
+type LessonIndexConfig struct {
+	MinLessons      int           `yaml:"min_lessons" json:"min_lessons,omitempty"`           // Embedded lessons a project needs to be indexed; 0 disables the index
+	RefreshInterval time.Duration `yaml:"refresh_interval" json:"refresh_interval,omitempty"` // Least time between incremental refreshes of a project's index; default 1m
+}
+
+// ChatConfig configures the read-only chat with a project's agent
+type ChatConfig struct {
+	MaxToolRounds int           `yaml:"max_tool_rounds" json:"max_tool_rounds,omitempty"` // Model calls running tools per message; default 8
+	IdleTimeout   time.Duration `yaml:"idle_timeout" json:"idle_timeout,omitempty"`       // Idle time after which a session is dropped; default 1h
+	MaxSessions   int           `yaml:"max_sessions" json:"max_sessions,omitempty"`       // Sessions kept at once; default 100
+}
+
+// DependencyUpdatesConfig configures the dependency update workflow, which
+// checks each cloned project for outdated Go modules and npm packages and
+// files a bead per ecosystem and semver risk, worked by a small model and
+// proposed as one pull request with the changelogs of the new releases.
+// Projects opt out with "dependency_updates": "false" in their context.
+type DependencyUpdatesConfig struct {
+	Enabled     bool          `yaml:"enabled" json:"enabled,omitempty"`
+	Interval    time.Duration `yaml:"interval" json:"interval,omitempty"`         // Time between checks; default 24h
+	ModelTier   string        `yaml:"model_tier" json:"model_tier,omitempty"`     // Model tier the beads are pinned to; default small
+	GitHubToken string        `yaml:"github_token" json:"github_token,omitempty"` // Read release notes under GitHub's higher rate limit; usually a secret: reference
+}
+
+// LanguageServersConfig configures the language servers (gopls and
+// others) agents query for definitions, references and renames. Each
+// project gets its own servers, started on first use.
+type LanguageServersConfig struct {
+	IdleTimeout time.Duration       `yaml:"idle_timeout" json:"idle_timeout,omitempty"` // Unused servers are stopped after this; default 15m
+	Servers     map[string][]string `yaml:"servers" json:"servers,omitempty"`           // Language -> command line, overriding the defaults
+}
+
+// JiraConfig is the Jira site projects sync with
+type JiraConfig struct {
+	URL      string `yaml:"url" json:"url,omitempty"`
+	Email    string `yaml:"email" json:"email,omitempty"`         // With an API token on Jira Cloud; empty uses a personal access token
+	APIToken string `yaml:"api_token" json:"api_token,omitempty"` // Usually a secret: reference
+}
+
+// IssueTrackerConfig is the tracker a project's beads sync with
+type IssueTrackerConfig struct {
+	Type       string   `yaml:"type" json:"type"`                                   // github or jira
+	Repository string   `yaml:"repository,omitempty" json:"repository,omitempty"`   // GitHub owner/name; defaults to the project's git_repo
+	ProjectKey string   `yaml:"project_key,omitempty" json:"project_key,omitempty"` // Jira project key
+	Labels     []string `yaml:"labels,omitempty" json:"labels,omitempty"`           // Only issues with one of these labels are imported; empty imports all
+}
+
+// VerificationConfig configures the test run a bead must pass before an
+// agent can close it. The run happens in the project's sandbox; failures
+// keep the bead open, are attached to it and get a follow-up bead.
+type VerificationConfig struct {
+	Enabled           bool          `yaml:"enabled" json:"enabled,omitempty"`
+	Command           string        `yaml:"command" json:"command,omitempty"`                       // Empty picks one for the project's test framework
+	CoverageThreshold float64       `yaml:"coverage_threshold" json:"coverage_threshold,omitempty"` // Percent; 0 does not gate on coverage
+	BeadTypes         []string      `yaml:"bead_types" json:"bead_types,omitempty"`                 // Empty verifies every type
+	Timeout           time.Duration `yaml:"timeout" json:"timeout,omitempty"`                       // Default 10m
 }
 
 // WebUIConfig configures the web interface
@@ -304,7 +888,16 @@ func DefaultConfig() *Config {
 			Mode: "block",
 		},
 		Dispatch: DispatchConfig{
-			MaxHops: 20,
+			MaxHops:    20,
+			MaxResumes: 3,
+		},
+		Sandbox: SandboxConfig{
+			Mode:          "local",
+			FallbackLocal: true,
+		},
+		Logging: LoggingConfig{
+			Level:  "info",
+			Format: "json",
 		},
 		Git: GitConfig{
 			ProjectKeyDir: "/app/data/projects",
@@ -315,6 +908,8 @@ func DefaultConfig() *Config {
 			RequireHTTPS:   false,
 			AllowedOrigins: []string{"*"},
 			JWTSecret:      "",
+
+			KeyRotationGracePeriod: 15 * time.Minute,
 		},
 		Temporal: TemporalConfig{
 			Host:                     "localhost:7233",
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redacted stands in for the value of a secret setting in a Change
+const redacted = "[redacted]"
+
+// Change is a setting whose value differs between two configurations
+type Change struct {
+	Field string      `json:"field"` // Dotted setting path; list items are indexed, e.g. providers[0].endpoint
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Diff returns the settings that differ from old to cfg, sorted by path.
+// The values of secrets such as API keys and tokens are redacted.
+func Diff(old, cfg *Config) []Change {
+	before := make(map[string]interface{})
+	after := make(map[string]interface{})
+	flatten(reflect.ValueOf(old), "", before)
+	flatten(reflect.ValueOf(cfg), "", after)
+
+	var changes []Change
+	for path, o := range before {
+		if n := after[path]; !reflect.DeepEqual(o, n) {
+			changes = append(changes, change(path, o, n))
+		}
+	}
+	for path, n := range after {
+		if _, ok := before[path]; !ok && n != nil {
+			changes = append(changes, change(path, nil, n))
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func change(path string, old, cfg interface{}) Change {
+	if secretSetting(path) {
+		if old != nil {
+			old = redacted
+		}
+		if cfg != nil {
+			cfg = redacted
+		}
+	}
+	return Change{Field: path, Old: old, New: cfg}
+}
+
+// secretSetting reports whether the setting at path holds a credential
+func secretSetting(path string) bool {
+	name := path[strings.LastIndex(path, ".")+1:]
+	switch name {
+	case "api_key", "dsn", "redis_url":
+		return true
+	}
+	return strings.Contains(name, "secret") || strings.Contains(name, "token") || strings.Contains(name, "password")
+}
+
+// flatten records the value of every setting under v by dotted path. Empty
+// values are recorded as nil so that unset and zero compare equal.
+func flatten(v reflect.Value, path string, out map[string]interface{}) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			out[path] = nil
+			return
+		}
+		flatten(v.Elem(), path, out)
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			out[path] = leaf(v)
+			return
+		}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if name := yamlName(t.Field(i)); name != "" {
+				flatten(v.Field(i), joinPath(path, name), out)
+			}
+		}
+	case reflect.Slice:
+		if elem := v.Type().Elem(); elem.Kind() == reflect.Struct || elem.Kind() == reflect.Ptr {
+			for i := 0; i < v.Len(); i++ {
+				flatten(v.Index(i), fmt.Sprintf("%s[%d]", path, i), out)
+			}
+			return
+		}
+		out[path] = leaf(v)
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			flatten(v.MapIndex(key), joinPath(path, fmt.Sprint(key.Interface())), out)
+		}
+	default:
+		out[path] = leaf(v)
+	}
+}
+
+// leaf is the value recorded for a setting: durations as "30s", and nil
+// for zero values
+func leaf(v reflect.Value) interface{} {
+	if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		return nil
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// CopySetting sets the setting at path in dst, a dotted path of keys such
+// as "dispatch" or "agents.max_concurrent", to its value in src
+func CopySetting(dst, src *Config, path string) error {
+	d, s := reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem()
+	for _, key := range strings.Split(path, ".") {
+		if d.Kind() != reflect.Struct {
+			return fmt.Errorf("no setting %s", path)
+		}
+		found := false
+		for i := 0; i < d.NumField(); i++ {
+			if yamlName(d.Type().Field(i)) == key {
+				d, s, found = d.Field(i), s.Field(i), true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no setting %s", path)
+		}
+	}
+	d.Set(s)
+	return nil
+}
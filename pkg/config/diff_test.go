@@ -0,0 +1,64 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := DefaultConfig()
+	old.Providers = []Provider{{ID: "a", Endpoint: "http://a", APIKey: "k1", Enabled: true}}
+	cfg := DefaultConfig()
+	cfg.Providers = []Provider{{ID: "a", Endpoint: "http://a2", APIKey: "k2", Enabled: true}, {ID: "b"}}
+	cfg.Dispatch.MaxHops = old.Dispatch.MaxHops + 5
+	cfg.Backup.Interval = 6 * time.Hour
+	cfg.Logging.Modules = map[string]string{"dispatch": "debug"}
+
+	if changes := Diff(old, old); len(changes) != 0 {
+		t.Fatalf("Diff of a config with itself = %v", changes)
+	}
+
+	got := make(map[string]Change)
+	for _, c := range Diff(old, cfg) {
+		got[c.Field] = c
+	}
+	want := map[string]Change{
+		"backup.interval":          {Old: nil, New: "6h0m0s"},
+		"dispatch.max_hops":        {Old: old.Dispatch.MaxHops, New: cfg.Dispatch.MaxHops},
+		"logging.modules.dispatch": {Old: nil, New: "debug"},
+		"providers[0].api_key":     {Old: redacted, New: redacted},
+		"providers[0].endpoint":    {Old: "http://a", New: "http://a2"},
+		"providers[1].id":          {Old: nil, New: "b"},
+	}
+	if len(got) != len(want) {
+		t.Errorf("Diff = %v", got)
+	}
+	for field, w := range want {
+		c, ok := got[field]
+		if !ok || c.Old != w.Old || c.New != w.New {
+			t.Errorf("%s: got %+v, want old %v new %v", field, c, w.Old, w.New)
+		}
+	}
+}
+
+func TestCopySetting(t *testing.T) {
+	dst, src := DefaultConfig(), DefaultConfig()
+	src.Agents.MaxConcurrent = 3
+	src.Agents.CorpProfile = "solo"
+	src.Readiness.Mode = "warn"
+
+	for _, path := range []string{"agents.max_concurrent", "readiness"} {
+		if err := CopySetting(dst, src, path); err != nil {
+			t.Fatalf("CopySetting(%s): %v", path, err)
+		}
+	}
+	if dst.Agents.MaxConcurrent != 3 || dst.Readiness.Mode != "warn" || dst.Agents.CorpProfile == "solo" {
+		t.Errorf("copied the wrong settings: agents %+v readiness %+v", dst.Agents, dst.Readiness)
+	}
+	if err := CopySetting(dst, src, "agents.max_concurrent.x"); err == nil {
+		t.Error("expected an error for a path below a value")
+	}
+	if err := CopySetting(dst, src, "nope"); err == nil {
+		t.Error("expected an error for an unknown setting")
+	}
+}
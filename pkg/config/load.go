@@ -0,0 +1,340 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix starts the name of every environment variable that overrides a
+// setting: server.http_port is LOOM_SERVER_HTTP_PORT
+const EnvPrefix = "LOOM_"
+
+// legacyEnv are environment variables honoured before the LOOM_ names
+// existed; the LOOM_ name wins when both are set
+var legacyEnv = map[string]string{
+	"TEMPORAL_HOST":      "temporal.host",
+	"TEMPORAL_NAMESPACE": "temporal.namespace",
+}
+
+// SearchPaths returns the files looked for, in order, when no config file
+// is named: config.yaml, config.yml or config.toml in the working
+// directory, then in the user's config directory under loom/, then in
+// /etc/loom.
+func SearchPaths() []string {
+	dirs := []string{"."}
+	if dir, err := os.UserConfigDir(); err == nil {
+		dirs = append(dirs, filepath.Join(dir, "loom"))
+	}
+	dirs = append(dirs, "/etc/loom")
+
+	var paths []string
+	for _, dir := range dirs {
+		for _, name := range []string{"config.yaml", "config.yml", "config.toml"} {
+			paths = append(paths, filepath.Join(dir, name))
+		}
+	}
+	return paths
+}
+
+// Find returns the config file to load. A path given with -config comes
+// first, then LOOM_CONFIG, then CONFIG_PATH; a file named any of these ways
+// must exist. Otherwise the first of SearchPaths that exists is used, and
+// an empty path means there is none.
+func Find(explicit string) (string, error) {
+	for _, p := range []string{explicit, os.Getenv(EnvPrefix + "CONFIG"), os.Getenv("CONFIG_PATH")} {
+		if p == "" {
+			continue
+		}
+		if _, err := os.Stat(p); err != nil {
+			return "", fmt.Errorf("config file %s: %w", p, err)
+		}
+		return p, nil
+	}
+	for _, p := range SearchPaths() {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", nil
+}
+
+// Load builds the configuration: the defaults, overlaid by the file at path
+// (YAML, or TOML when it ends in .toml; none when path is empty), overlaid
+// by LOOM_ environment variables. The result is validated. Keys in the file
+// that match no setting are logged and otherwise ignored.
+func Load(path string) (*Config, error) {
+	cfg, unknown, err := load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range unknown {
+		log.Printf("Warning: %s: unknown setting %s ignored", path, key)
+	}
+	return cfg, nil
+}
+
+// Check loads the configuration as Load does, but also treats keys that
+// match no setting as problems. It is what `loom config validate` runs.
+func Check(path string) (*Config, error) {
+	cfg, unknown, err := load(path)
+	var ve *ValidationError
+	if err != nil && !errors.As(err, &ve) {
+		return nil, err
+	}
+	var problems []Problem
+	for _, key := range unknown {
+		problems = append(problems, Problem{Field: key, Message: "unknown setting"})
+	}
+	if ve != nil {
+		problems = append(problems, ve.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Source: sourceName(path), Problems: problems}
+	}
+	return cfg, nil
+}
+
+// load applies the layers and validates the result, returning the unknown
+// keys of the file
+func load(path string) (*Config, []string, error) {
+	cfg := DefaultConfig()
+	var unknown []string
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		if unknown, err = decode(path, data, cfg); err != nil {
+			return nil, nil, err
+		}
+	}
+	problems := applyEnv(cfg, os.Getenv)
+	problems = append(problems, cfg.problems()...)
+	if len(problems) > 0 {
+		return nil, unknown, &ValidationError{Source: sourceName(path), Problems: problems}
+	}
+	return cfg, unknown, nil
+}
+
+// decode parses a YAML or TOML file over cfg. ${VAR} references are
+// expanded first. TOML is converted to YAML so both formats share the yaml
+// field names and value parsing, such as durations written "30s".
+func decode(path string, data []byte, cfg *Config) ([]string, error) {
+	expanded := []byte(os.ExpandEnv(string(data)))
+
+	var raw map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		if _, err := toml.Decode(string(expanded), &raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		converted, err := yaml.Marshal(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		expanded = converted
+	} else if err := yaml.Unmarshal(expanded, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(expanded, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	unknown := unknownKeys(reflect.TypeOf(*cfg), raw, "")
+	sort.Strings(unknown)
+	return unknown, nil
+}
+
+// unknownKeys returns the dotted paths of keys in m that no yaml-tagged
+// field of struct type t accepts
+func unknownKeys(t reflect.Type, m map[string]interface{}, prefix string) []string {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		if name := yamlName(t.Field(i)); name != "" {
+			fields[name] = t.Field(i).Type
+		}
+	}
+
+	var unknown []string
+	for key, value := range m {
+		ft, ok := fields[key]
+		if !ok {
+			unknown = append(unknown, prefix+key)
+			continue
+		}
+		for ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		switch {
+		case ft.Kind() == reflect.Struct && ft != reflect.TypeOf(time.Time{}):
+			if sub, ok := value.(map[string]interface{}); ok {
+				unknown = append(unknown, unknownKeys(ft, sub, prefix+key+".")...)
+			}
+		case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Struct:
+			for i, item := range tableList(value) {
+				unknown = append(unknown, unknownKeys(ft.Elem(), item, fmt.Sprintf("%s%s[%d].", prefix, key, i))...)
+			}
+		}
+	}
+	return unknown
+}
+
+// tableList returns the maps of a YAML sequence or TOML array of tables
+func tableList(v interface{}) []map[string]interface{} {
+	switch list := v.(type) {
+	case []map[string]interface{}:
+		return list
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(list))
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// yamlName is the key a field is read from, or "" if it is not read
+func yamlName(f reflect.StructField) string {
+	if !f.IsExported() {
+		return ""
+	}
+	name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+	switch name {
+	case "-":
+		return ""
+	case "":
+		return strings.ToLower(f.Name) // yaml.v3's default
+	}
+	return name
+}
+
+// EnvVars returns the environment variable of every setting it can
+// override, mapped to the setting's dotted path
+func EnvVars() map[string]string {
+	vars := make(map[string]string)
+	walkEnv(reflect.ValueOf(DefaultConfig()).Elem(), "", func(path string, _ reflect.Value) {
+		vars[envName(path)] = path
+	})
+	return vars
+}
+
+// applyEnv overrides settings of cfg from the environment: legacy names
+// first, then LOOM_ names. Values that do not parse are returned as problems.
+func applyEnv(cfg *Config, getenv func(string) string) []Problem {
+	values := make(map[string]string)
+	for name, path := range legacyEnv {
+		if v := getenv(name); v != "" {
+			values[path] = v
+		}
+	}
+
+	var problems []Problem
+	walkEnv(reflect.ValueOf(cfg).Elem(), "", func(path string, field reflect.Value) {
+		name := envName(path)
+		v, ok := values[path]
+		if env := getenv(name); env != "" {
+			v, ok = env, true
+		}
+		if !ok {
+			return
+		}
+		if err := setField(field, v); err != nil {
+			problems = append(problems, Problem{Field: name, Message: err.Error()})
+		}
+	})
+	return problems
+}
+
+// walkEnv calls fn for every setting under v that an environment variable
+// can hold: strings, numbers, booleans, durations and lists of strings.
+// Maps and lists of structs, such as projects, are only set in the file.
+func walkEnv(v reflect.Value, prefix string, fn func(path string, field reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		name := yamlName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			walkEnv(field, prefix+name+".", fn)
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+			fn(prefix+name, field)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() == reflect.String {
+				fn(prefix+name, field)
+			}
+		}
+	}
+}
+
+// envName is the environment variable for a dotted setting path
+func envName(path string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(path, ".", "_"))
+}
+
+// setField parses s into field. Lists are comma-separated.
+func setField(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("must be true or false, got %q", s)
+		}
+		field.SetBool(b)
+	case reflect.Int64:
+		if field.Type() == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				return fmt.Errorf("must be a duration such as 30s or 5m, got %q", s)
+			}
+			field.SetInt(int64(d))
+			return nil
+		}
+		fallthrough
+	case reflect.Int:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return fmt.Errorf("must be a whole number, got %q", s)
+		}
+		field.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("must be a number, got %q", s)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	}
+	return nil
+}
+
+func sourceName(path string) string {
+	if path == "" {
+		return "configuration"
+	}
+	return path
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load("")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.HTTPPort != 8080 || cfg.Database.Type != "sqlite" {
+		t.Errorf("expected defaults, got port %d database %q", cfg.Server.HTTPPort, cfg.Database.Type)
+	}
+}
+
+func TestLoadYAMLAndTOML(t *testing.T) {
+	yamlPath := writeFile(t, "config.yaml", `
+server:
+  http_port: 9090
+  read_timeout: 45s
+agents:
+  allowed_roles: [ceo, qa]
+projects:
+  - id: p1
+    name: One
+    git_repo: git@example.com:one.git
+`)
+	tomlPath := writeFile(t, "config.toml", `
+[server]
+http_port = 9090
+read_timeout = "45s"
+
+[agents]
+allowed_roles = ["ceo", "qa"]
+
+[[projects]]
+id = "p1"
+name = "One"
+git_repo = "git@example.com:one.git"
+`)
+	for _, path := range []string{yamlPath, tomlPath} {
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("Load(%s): %v", path, err)
+		}
+		if cfg.Server.HTTPPort != 9090 || cfg.Server.ReadTimeout != 45*time.Second {
+			t.Errorf("%s: server = %+v", path, cfg.Server)
+		}
+		if cfg.Server.HTTPSPort != 8443 || cfg.Temporal.TaskQueue != "loom-tasks" {
+			t.Errorf("%s: unset settings should keep their defaults", path)
+		}
+		if len(cfg.Agents.AllowedRoles) != 2 || len(cfg.Projects) != 1 || cfg.Projects[0].GitRepo != "git@example.com:one.git" {
+			t.Errorf("%s: agents %+v projects %+v", path, cfg.Agents, cfg.Projects)
+		}
+	}
+}
+
+func TestEnvOverlay(t *testing.T) {
+	path := writeFile(t, "config.yaml", "server:\n  http_port: 9090\ntemporal:\n  host: file:7233\n")
+	t.Setenv("LOOM_SERVER_HTTP_PORT", "9191")
+	t.Setenv("LOOM_BACKUP_INTERVAL", "6h")
+	t.Setenv("LOOM_SECURITY_ALLOWED_ORIGINS", "https://a.example, https://b.example")
+	t.Setenv("LOOM_SECURITY_OIDC_CLIENT_ID", "loom")
+	t.Setenv("TEMPORAL_HOST", "legacy:7233")
+	t.Setenv("TEMPORAL_NAMESPACE", "legacy")
+	t.Setenv("LOOM_TEMPORAL_NAMESPACE", "prod")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Server.HTTPPort != 9191 || cfg.Backup.Interval != 6*time.Hour || cfg.Security.OIDC.ClientID != "loom" {
+		t.Errorf("environment not applied: port %d interval %v client %q", cfg.Server.HTTPPort, cfg.Backup.Interval, cfg.Security.OIDC.ClientID)
+	}
+	if got := cfg.Security.AllowedOrigins; len(got) != 2 || got[1] != "https://b.example" {
+		t.Errorf("allowed_origins = %q", got)
+	}
+	if cfg.Temporal.Host != "legacy:7233" || cfg.Temporal.Namespace != "prod" {
+		t.Errorf("temporal = %s/%s, want legacy:7233/prod", cfg.Temporal.Host, cfg.Temporal.Namespace)
+	}
+
+	if vars := EnvVars(); vars["LOOM_SERVER_HTTP_PORT"] != "server.http_port" || vars["LOOM_SECURITY_OIDC_GROUPS_CLAIM"] == "" {
+		t.Errorf("EnvVars missing settings")
+	}
+
+	t.Setenv("LOOM_SERVER_HTTP_PORT", "eighty")
+	var ve *ValidationError
+	if _, err := Load(path); !errors.As(err, &ve) || ve.Problems[0].Field != "LOOM_SERVER_HTTP_PORT" {
+		t.Fatalf("expected a problem naming the variable, got %v", err)
+	}
+}
+
+func TestValidationProblems(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  http_port: 70000
+  enable_https: true
+database:
+  type: mysql
+logging:
+  level: verbose
+projects:
+  - id: p1
+  - id: p1
+    git_strategy: yolo
+`)
+	_, err := Load(path)
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	got := make(map[string]bool)
+	for _, p := range ve.Problems {
+		got[p.Field] = true
+	}
+	for _, field := range []string{"server.http_port", "server.tls_cert_file", "server.tls_key_file", "database.type", "logging.level", "projects[1].id", "projects[1].git_strategy"} {
+		if !got[field] {
+			t.Errorf("no problem reported for %s in %v", field, ve.Problems)
+		}
+	}
+	if !strings.Contains(err.Error(), path) || !strings.Contains(err.Error(), `must be one of sqlite, postgres; got "mysql"`) {
+		t.Errorf("unhelpful error: %v", err)
+	}
+
+	if _, err := Load(writeFile(t, "bad.yaml", "server:\n  http_port: [1\n")); err == nil || errors.As(err, &ve) {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+}
+
+func TestActivityPolicies(t *testing.T) {
+	cfg, err := Load(writeFile(t, "config.yaml", `
+temporal:
+  activities:
+    LoomHeartbeatActivity:
+      heartbeat_timeout: 30s
+      maximum_attempts: 3
+      backoff_coefficient: 1.5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := cfg.Temporal.ActivityPolicy("LoomHeartbeatActivity")
+	if p.HeartbeatTimeout != 30*time.Second || p.MaximumAttempts != 3 || p.BackoffCoefficient != 1.5 || p.StartToCloseTimeout != 10*time.Minute {
+		t.Errorf("configured policy = %+v", p)
+	}
+	if p := cfg.Temporal.ActivityPolicy("ProviderQueryActivity"); p.MaximumAttempts != 2 || p.StartToCloseTimeout != 3*time.Minute {
+		t.Errorf("default policy = %+v", p)
+	}
+
+	_, err = Load(writeFile(t, "bad.yaml", `
+temporal:
+  activities:
+    HeartbeatActivity:
+      maximum_attempts: 2
+    ProviderQueryActivity:
+      backoff_coefficient: 0.5
+`))
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 2 || ve.Problems[0].Field != "temporal.activities.HeartbeatActivity" || ve.Problems[1].Field != "temporal.activities.ProviderQueryActivity.backoff_coefficient" {
+		t.Errorf("expected problems with the unknown activity and the backoff, got %v", err)
+	}
+}
+
+func TestCheckReportsUnknownKeys(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+server:
+  http_prot: 9090
+security:
+  oidc:
+    enabled: false
+    clientid: x
+projects:
+  - id: p1
+    brnach: main
+`)
+	if _, err := Load(path); err != nil {
+		t.Fatalf("Load should ignore unknown keys: %v", err)
+	}
+	_, err := Check(path)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 3 {
+		t.Fatalf("Check = %v", err)
+	}
+	for i, want := range []string{"projects[0].brnach", "security.oidc.clientid", "server.http_prot"} {
+		if ve.Problems[i].Field != want || ve.Problems[i].Message != "unknown setting" {
+			t.Errorf("problem %d = %v, want %s", i, ve.Problems[i], want)
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	dir := t.TempDir()
+	wd, _ := os.Getwd()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	t.Setenv("LOOM_CONFIG", "")
+	t.Setenv("CONFIG_PATH", "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+	t.Setenv("HOME", dir)
+
+	if path, err := Find(""); err != nil || (path != "" && !strings.HasPrefix(path, "/etc/loom")) {
+		t.Fatalf("Find with no files = %q, %v", path, err)
+	}
+	if err := os.WriteFile("config.toml", []byte(""), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := Find(""); path != "config.toml" {
+		t.Errorf("Find = %q, want config.toml", path)
+	}
+	if err := os.WriteFile("config.yaml", []byte(""), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := Find(""); path != "config.yaml" {
+		t.Errorf("Find = %q, want config.yaml before config.toml", path)
+	}
+
+	other := writeFile(t, "other.yaml", "")
+	t.Setenv("LOOM_CONFIG", other)
+	if path, _ := Find(""); path != other {
+		t.Errorf("Find = %q, want LOOM_CONFIG %s", path, other)
+	}
+	if path, _ := Find("config.toml"); path != "config.toml" {
+		t.Errorf("Find = %q, want the explicit path", path)
+	}
+	if _, err := Find("missing.yaml"); err == nil {
+		t.Error("expected an error for a missing explicit file")
+	}
+}
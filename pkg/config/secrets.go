@@ -0,0 +1,80 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SecretPrefix marks a setting whose value is a secret in the key store,
+// named after the prefix: api_key: secret:openai-api-key
+const SecretPrefix = "secret:"
+
+// SecretRefs returns the settings that reference a secret, mapped to the
+// secret's name
+func (c *Config) SecretRefs() map[string]string {
+	refs := make(map[string]string)
+	walkStrings(reflect.ValueOf(c).Elem(), "", func(path string, s string, _ func(string)) {
+		if name, ok := strings.CutPrefix(s, SecretPrefix); ok {
+			refs[path] = name
+		}
+	})
+	return refs
+}
+
+// ResolveSecrets replaces every secret reference in c with the secret's
+// value from lookup. References that do not resolve are left in place and
+// reported together.
+func (c *Config) ResolveSecrets(lookup func(name string) (string, error)) error {
+	var problems []Problem
+	walkStrings(reflect.ValueOf(c).Elem(), "", func(path string, s string, set func(string)) {
+		name, ok := strings.CutPrefix(s, SecretPrefix)
+		if !ok {
+			return
+		}
+		value, err := lookup(name)
+		if err != nil {
+			problems = append(problems, Problem{Field: path, Message: fmt.Sprintf("secret %q: %v", name, err)})
+			return
+		}
+		set(value)
+	})
+	if len(problems) > 0 {
+		return &ValidationError{Source: "configuration", Problems: problems}
+	}
+	return nil
+}
+
+// walkStrings calls fn with every string setting under v, including items
+// of lists and values of maps, and a function that replaces it
+func walkStrings(v reflect.Value, path string, fn func(path string, s string, set func(string))) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if name := yamlName(t.Field(i)); name != "" {
+				walkStrings(v.Field(i), joinPath(path, name), fn)
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case reflect.Map:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range v.MapKeys() {
+			key := key
+			fn(joinPath(path, fmt.Sprint(key.Interface())), v.MapIndex(key).String(), func(s string) {
+				v.SetMapIndex(key, reflect.ValueOf(s).Convert(v.Type().Elem()))
+			})
+		}
+	case reflect.String:
+		fn(path, v.String(), v.SetString)
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+security:
+  webhook_secret: secret:github-webhook
+  api_keys: [plain, "secret:ci-key"]
+providers:
+  - id: openai
+    api_key: secret:openai-key
+    enabled: true
+  - id: local
+    api_key: not-a-reference
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	refs := cfg.SecretRefs()
+	if len(refs) != 3 || refs["providers[0].api_key"] != "openai-key" || refs["security.api_keys[1]"] != "ci-key" {
+		t.Fatalf("SecretRefs = %v", refs)
+	}
+
+	store := map[string]string{"github-webhook": "whsec", "ci-key": "ci", "openai-key": "sk-123"}
+	lookup := func(name string) (string, error) {
+		if v, ok := store[name]; ok {
+			return v, nil
+		}
+		return "", fmt.Errorf("key not found: %s", name)
+	}
+	if err := cfg.ResolveSecrets(lookup); err != nil {
+		t.Fatalf("ResolveSecrets: %v", err)
+	}
+	if cfg.Security.WebhookSecret != "whsec" || cfg.Security.APIKeys[1] != "ci" || cfg.Providers[0].APIKey != "sk-123" || cfg.Providers[1].APIKey != "not-a-reference" {
+		t.Errorf("secrets not resolved: security %+v providers %+v", cfg.Security, cfg.Providers)
+	}
+	if len(cfg.SecretRefs()) != 0 {
+		t.Error("resolved references should be gone")
+	}
+
+	cfg.OpenClaw.HookToken = "secret:missing"
+	err = cfg.ResolveSecrets(lookup)
+	var ve *ValidationError
+	if !errors.As(err, &ve) || len(ve.Problems) != 1 || ve.Problems[0].Field != "openclaw.hook_token" {
+		t.Fatalf("expected a problem naming the setting, got %v", err)
+	}
+}
@@ -0,0 +1,289 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Problem is one setting that is wrong
+type Problem struct {
+	Field   string `json:"field"` // Dotted setting path, or the environment variable it came from
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	return p.Field + ": " + p.Message
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Source   string // The config file, or "configuration" when there is none
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Problems) == 1 {
+		return fmt.Sprintf("%s: %s", e.Source, e.Problems[0])
+	}
+	lines := make([]string, 0, len(e.Problems)+1)
+	lines = append(lines, fmt.Sprintf("%s: %d problems:", e.Source, len(e.Problems)))
+	for _, p := range e.Problems {
+		lines = append(lines, "  "+p.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks that settings have values Loom can use and that settings
+// needed together are set together
+func (c *Config) Validate() error {
+	if problems := c.problems(); len(problems) > 0 {
+		return &ValidationError{Source: "configuration", Problems: problems}
+	}
+	return nil
+}
+
+// problems returns everything wrong with c
+func (c *Config) problems() []Problem {
+	var v validator
+
+	v.port("server.http_port", c.Server.HTTPPort)
+	v.port("server.https_port", c.Server.HTTPSPort)
+	if c.Server.EnableHTTPS {
+		v.required("server.tls_cert_file", c.Server.TLSCertFile, "when enable_https is true")
+		v.required("server.tls_key_file", c.Server.TLSKeyFile, "when enable_https is true")
+	}
+	v.notNegative("server.read_timeout", int64(c.Server.ReadTimeout))
+	v.notNegative("server.write_timeout", int64(c.Server.WriteTimeout))
+	v.notNegative("server.idle_timeout", int64(c.Server.IdleTimeout))
+
+	switch c.Database.Type {
+	case "":
+	case "sqlite":
+		v.required("database.path", c.Database.Path, "for a sqlite database")
+	case "postgres":
+		v.required("database.dsn", c.Database.DSN, "for a postgres database")
+	default:
+		v.oneOf("database.type", c.Database.Type, "sqlite", "postgres")
+	}
+	v.notNegative("database.max_open_conns", int64(c.Database.MaxOpenConns))
+	v.notNegative("database.max_idle_conns", int64(c.Database.MaxIdleConns))
+
+	v.oneOf("beads.backend", c.Beads.Backend, "", "sqlite", "dolt")
+	v.oneOf("beads.federation.sync_strategy", c.Beads.Federation.SyncStrategy, "", "ours", "theirs")
+	v.oneOf("beads.federation.sync_mode", c.Beads.Federation.SyncMode, "", "dolt-native", "belt-and-suspenders")
+	for i, peer := range c.Beads.Federation.Peers {
+		v.required(fmt.Sprintf("beads.federation.peers[%d].remote_url", i), peer.RemoteURL, "")
+	}
+
+	v.notNegative("agents.max_concurrent", int64(c.Agents.MaxConcurrent))
+	v.oneOf("agents.corp_profile", strings.ToLower(c.Agents.CorpProfile), "", "full", "enterprise", "startup", "solo")
+	v.oneOf("readiness.mode", c.Readiness.Mode, "", "block", "warn")
+	v.notNegative("dispatch.max_hops", int64(c.Dispatch.MaxHops))
+	v.notNegative("dispatch.max_resumes", int64(c.Dispatch.MaxResumes))
+
+	if c.Security.OIDC.Enabled {
+		v.required("security.oidc.issuer_url", c.Security.OIDC.IssuerURL, "when oidc is enabled")
+		v.required("security.oidc.client_id", c.Security.OIDC.ClientID, "when oidc is enabled")
+		v.required("security.oidc.redirect_url", c.Security.OIDC.RedirectURL, "when oidc is enabled")
+	}
+	v.notNegative("security.key_rotation_grace_period", int64(c.Security.KeyRotationGracePeriod))
+	v.oneOf("security.key_store.backend", c.Security.KeyStore.Backend, "", "password", "vault", "awskms", "gcpkms")
+	if b := c.Security.KeyStore.Backend; b != "" && b != "password" {
+		v.required("security.key_store.key_id", c.Security.KeyStore.KeyID, "for the "+b+" backend")
+	}
+
+	activities := make([]string, 0, len(c.Temporal.Activities))
+	for name := range c.Temporal.Activities {
+		activities = append(activities, name)
+	}
+	sort.Strings(activities)
+	for _, name := range activities {
+		v.activityPolicy("temporal.activities."+name, name, c.Temporal.Activities[name])
+	}
+
+	v.notNegative("models.queue.max_concurrent", int64(c.Models.Queue.MaxConcurrent))
+	v.notNegative("models.queue.max_depth", int64(c.Models.Queue.MaxDepth))
+	queued := make([]string, 0, len(c.Models.Queue.Providers))
+	for id := range c.Models.Queue.Providers {
+		queued = append(queued, id)
+	}
+	sort.Strings(queued)
+	for _, id := range queued {
+		v.notNegative("models.queue.providers."+id, int64(c.Models.Queue.Providers[id]))
+	}
+
+	v.notNegative("models.saturation.max_waiting", int64(c.Models.Saturation.MaxWaiting))
+	if u := c.Models.Saturation.MaxCacheUsage; u < 0 || u > 1 {
+		v.add("models.saturation.max_cache_usage", "must be between 0 and 1, got %g", u)
+	}
+
+	v.notNegative("embedding.cache_size", int64(c.Embedding.CacheSize))
+	v.notNegative("embedding.cache_ttl", int64(c.Embedding.CacheTTL))
+	v.notNegative("lessons.search.page_size", int64(c.Lessons.Search.PageSize))
+	v.notNegative("lessons.search.max_candidates", int64(c.Lessons.Search.MaxCandidates))
+	v.notNegative("lessons.search.max_scans", int64(c.Lessons.Search.MaxScans))
+	if c.Lessons.Search.ScanRate < 0 {
+		v.add("lessons.search.scan_rate", "must not be negative, got %g", c.Lessons.Search.ScanRate)
+	}
+	v.notNegative("lessons.index.min_lessons", int64(c.Lessons.Index.MinLessons))
+	v.notNegative("lessons.index.refresh_interval", int64(c.Lessons.Index.RefreshInterval))
+
+	v.oneOf("scheduler.backend", c.Scheduler.Backend, "", "temporal", "embedded")
+	v.notNegative("scheduler.poll_interval", int64(c.Scheduler.PollInterval))
+
+	if c.Cache.Enabled {
+		v.oneOf("cache.backend", c.Cache.Backend, "", "memory", "redis")
+		if c.Cache.Backend == "redis" {
+			v.required("cache.redis_url", c.Cache.RedisURL, "for the redis cache backend")
+		}
+		v.oneOf("cache.compression", c.Cache.Compression, "", "gzip", "zstd")
+		v.notNegative("cache.max_memory_mb", int64(c.Cache.MaxMemoryMB))
+		if c.Cache.Keys.IgnoreTemperatureBelow < 0 {
+			v.add("cache.keys.ignore_temperature_below", "must not be negative")
+		}
+	}
+
+	v.sandbox("sandbox", c.Sandbox)
+	v.oneOf("logging.level", strings.ToLower(c.Logging.Level), "", "debug", "info", "warn", "warning", "error")
+	v.oneOf("logging.format", c.Logging.Format, "", "json", "text")
+	modules := make([]string, 0, len(c.Logging.Modules))
+	for module := range c.Logging.Modules {
+		modules = append(modules, module)
+	}
+	sort.Strings(modules)
+	for _, module := range modules {
+		v.oneOf("logging.modules."+module, strings.ToLower(c.Logging.Modules[module]), "debug", "info", "warn", "warning", "error")
+	}
+
+	v.notNegative("backup.keep", int64(c.Backup.Keep))
+	v.notNegative("backup.interval", int64(c.Backup.Interval))
+	v.notNegative("backup.max_age", int64(c.Backup.MaxAge))
+
+	ret := c.Activity.Retention
+	v.notNegative("activity.retention.raw_retention", int64(ret.RawRetention))
+	v.notNegative("activity.retention.rollup_retention", int64(ret.RollupRetention))
+	v.notNegative("activity.retention.interval", int64(ret.Interval))
+	v.notNegative("activity.retention.batch_size", int64(ret.BatchSize))
+	if ret.ArchiveS3 != nil {
+		v.required("activity.retention.archive_s3.bucket", ret.ArchiveS3.Bucket, "when archive_s3 is set")
+	}
+	eventTypes := make([]string, 0, len(c.Activity.Aggregation))
+	for eventType := range c.Activity.Aggregation {
+		eventTypes = append(eventTypes, eventType)
+	}
+	sort.Strings(eventTypes)
+	for _, eventType := range eventTypes {
+		rule := c.Activity.Aggregation[eventType]
+		field := "activity.aggregation." + eventType
+		v.notNegative(field+".window", int64(rule.Window))
+		v.notNegative(field+".max_events", int64(rule.MaxEvents))
+		for i, g := range rule.GroupBy {
+			v.oneOf(fmt.Sprintf("%s.group_by[%d]", field, i), g, "project", "actor", "agent", "bead", "resource")
+		}
+	}
+
+	push := c.Notifications.Push
+	if push.FCM != nil {
+		v.required("notifications.push.fcm.credentials_file", push.FCM.CredentialsFile, "when fcm is set")
+	}
+	if push.APNs != nil {
+		v.required("notifications.push.apns.key_file", push.APNs.KeyFile, "when apns is set")
+		v.required("notifications.push.apns.key_id", push.APNs.KeyID, "when apns is set")
+		v.required("notifications.push.apns.team_id", push.APNs.TeamID, "when apns is set")
+		v.required("notifications.push.apns.topic", push.APNs.Topic, "when apns is set")
+	}
+
+	if c.OpenClaw.Enabled {
+		v.required("openclaw.gateway_url", c.OpenClaw.GatewayURL, "when openclaw is enabled")
+	}
+
+	seen := make(map[string]bool)
+	for i, p := range c.Projects {
+		field := fmt.Sprintf("projects[%d]", i)
+		v.required(field+".id", p.ID, "")
+		if p.ID != "" && seen[p.ID] {
+			v.add(field+".id", "duplicate project id %q", p.ID)
+		}
+		seen[p.ID] = true
+		v.oneOf(field+".git_strategy", p.GitStrategy, "", "direct", "branch-pr")
+		if p.Sandbox != nil {
+			v.sandbox(field+".sandbox", *p.Sandbox)
+		}
+	}
+	for i, p := range c.Providers {
+		v.required(fmt.Sprintf("providers[%d].id", i), p.ID, "")
+	}
+
+	return v.problems
+}
+
+// validator collects problems
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(field, value, when string) {
+	if strings.TrimSpace(value) != "" {
+		return
+	}
+	if when == "" {
+		v.add(field, "is required")
+		return
+	}
+	v.add(field, "is required %s", when)
+}
+
+func (v *validator) oneOf(field, value string, allowed ...string) {
+	var named []string
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+		if a != "" {
+			named = append(named, a)
+		}
+	}
+	v.add(field, "must be one of %s; got %q", strings.Join(named, ", "), value)
+}
+
+func (v *validator) port(field string, port int) {
+	if port < 0 || port > 65535 {
+		v.add(field, "must be between 0 and 65535, got %d", port)
+	}
+}
+
+func (v *validator) notNegative(field string, n int64) {
+	if n < 0 {
+		v.add(field, "must not be negative")
+	}
+}
+
+func (v *validator) activityPolicy(field, name string, p ActivityPolicy) {
+	if _, ok := defaultActivityPolicies[name]; !ok {
+		known := make([]string, 0, len(defaultActivityPolicies))
+		for n := range defaultActivityPolicies {
+			known = append(known, n)
+		}
+		sort.Strings(known)
+		v.oneOf(field, name, known...)
+		return
+	}
+	v.notNegative(field+".start_to_close_timeout", int64(p.StartToCloseTimeout))
+	v.notNegative(field+".heartbeat_timeout", int64(p.HeartbeatTimeout))
+	v.notNegative(field+".maximum_attempts", int64(p.MaximumAttempts))
+	v.notNegative(field+".initial_interval", int64(p.InitialInterval))
+	v.notNegative(field+".maximum_interval", int64(p.MaximumInterval))
+	if p.BackoffCoefficient != 0 && p.BackoffCoefficient < 1 {
+		v.add(field+".backoff_coefficient", "must be at least 1, got %g", p.BackoffCoefficient)
+	}
+}
+
+func (v *validator) sandbox(field string, s SandboxConfig) {
+	v.oneOf(field+".mode", s.Mode, "", "local", "docker", "podman")
+	v.oneOf(field+".network", s.Network, "", "none", "bridge", "host")
+}
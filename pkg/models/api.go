@@ -0,0 +1,166 @@
+package models
+
+// Request and response bodies of the HTTP API. They are shared by the
+// server handlers, the generated OpenAPI document and the Go client in
+// pkg/client, so the three cannot drift apart.
+
+// CreateBeadRequest is the body of POST /api/v1/beads
+type CreateBeadRequest struct {
+	Type        string            `json:"type,omitempty"` // Default "task"
+	Title       string            `json:"title"`
+	Description string            `json:"description,omitempty"`
+	Priority    int               `json:"priority,omitempty"` // Default 2
+	ProjectID   string            `json:"project_id"`
+	Parent      string            `json:"parent,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Context     map[string]string `json:"context,omitempty"`
+}
+
+// UpdateBeadRequest is the body of PATCH /api/v1/beads/{id}. Only the
+// fields that are set are changed.
+type UpdateBeadRequest struct {
+	Title       *string           `json:"title,omitempty"`
+	Type        *string           `json:"type,omitempty"`
+	Status      *string           `json:"status,omitempty"`
+	Priority    *int              `json:"priority,omitempty"`
+	ProjectID   *string           `json:"project_id,omitempty"`
+	AssignedTo  *string           `json:"assigned_to,omitempty"`
+	Description *string           `json:"description,omitempty"`
+	Parent      *string           `json:"parent,omitempty"`
+	Tags        *[]string         `json:"tags,omitempty"`
+	BlockedBy   *[]string         `json:"blocked_by,omitempty"`
+	Blocks      *[]string         `json:"blocks,omitempty"`
+	RelatedTo   *[]string         `json:"related_to,omitempty"`
+	Children    *[]string         `json:"children,omitempty"`
+	Context     map[string]string `json:"context,omitempty"`
+}
+
+// ClaimBeadRequest is the body of POST /api/v1/beads/{id}/claim
+type ClaimBeadRequest struct {
+	AgentID string `json:"agent_id"`
+}
+
+// RejectPlanRequest is the body of POST /api/v1/beads/{id}/plan/reject
+type RejectPlanRequest struct {
+	Reason string `json:"reason"`
+}
+
+// UndoBeadRequest is the body of POST /api/v1/beads/{id}/undo
+type UndoBeadRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// OnboardingScanRequest is the body of POST
+// /api/v1/projects/{id}/onboarding-scan
+type OnboardingScanRequest struct {
+	// MaxBeads caps the proposed backlog; 0 uses the default of 30
+	MaxBeads int `json:"max_beads,omitempty"`
+	// DryRun returns the proposed beads without filing them
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// DependencyUpdatesRequest is the body of POST
+// /api/v1/projects/{id}/dependency-updates
+type DependencyUpdatesRequest struct {
+	// DryRun returns the update groups without filing their beads
+	DryRun bool `json:"dry_run,omitempty"`
+}
+
+// RejectPatchRequest is the body of POST
+// /api/v1/projects/{id}/patches/{patch_id}/reject
+type RejectPatchRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ApproveLowRiskPatchesRequest is the body of POST
+// /api/v1/projects/{id}/patches/approve-low-risk
+type ApproveLowRiskPatchesRequest struct {
+	Globs []string `json:"globs,omitempty"` // Defaults to the project's low-risk globs
+}
+
+// CreateProjectRequest is the body of POST /api/v1/projects
+type CreateProjectRequest struct {
+	Name      string            `json:"name"`
+	GitRepo   string            `json:"git_repo"`
+	Branch    string            `json:"branch"`
+	BeadsPath string            `json:"beads_path,omitempty"`
+	Context   map[string]string `json:"context,omitempty"`
+	IsSticky  *bool             `json:"is_sticky,omitempty"`
+	OrgID     string            `json:"org_id,omitempty"` // Owning organization; defaults to the caller's
+
+	Compliance *ComplianceConstraints `json:"compliance,omitempty"`
+}
+
+// UpdateProjectRequest is the body of PUT /api/v1/projects/{id}. Empty and
+// unset fields are left unchanged.
+type UpdateProjectRequest struct {
+	Name        string            `json:"name,omitempty"`
+	GitRepo     string            `json:"git_repo,omitempty"`
+	Branch      string            `json:"branch,omitempty"`
+	BeadsPath   string            `json:"beads_path,omitempty"`
+	Context     map[string]string `json:"context,omitempty"`
+	Status      string            `json:"status,omitempty"`
+	GitStrategy *string           `json:"git_strategy,omitempty"`
+	IsPerpetual *bool             `json:"is_perpetual,omitempty"`
+	IsSticky    *bool             `json:"is_sticky,omitempty"`
+
+	// Compliance replaces the project's constraints; send an empty object to clear them
+	Compliance *ComplianceConstraints `json:"compliance,omitempty"`
+	// SharedLessonsOptOut keeps the organization's shared lessons out of the project's prompts
+	SharedLessonsOptOut *bool `json:"shared_lessons_opt_out,omitempty"`
+}
+
+// PromoteLessonRequest is the body of POST
+// /api/v1/projects/{id}/lessons/{lesson_id}/promote. Empty fields keep the
+// project lesson's wording.
+type PromoteLessonRequest struct {
+	Title  string `json:"title,omitempty"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// SharedLessonRequest is the body of POST /api/v1/orgs/{id}/lessons
+type SharedLessonRequest struct {
+	Category string `json:"category,omitempty"` // "insight" by default
+	Title    string `json:"title"`
+	Detail   string `json:"detail"`
+}
+
+// ChatRequest is the body of POST /api/v1/projects/{id}/chat
+type ChatRequest struct {
+	SessionID string `json:"session_id,omitempty"` // Empty starts a session
+	Message   string `json:"message"`
+	Stream    bool   `json:"stream,omitempty"` // Answer as server-sent events
+}
+
+// SpawnAgentRequest is the body of POST /api/v1/agents
+type SpawnAgentRequest struct {
+	Name        string `json:"name,omitempty"`
+	PersonaName string `json:"persona_name"`
+	ProjectID   string `json:"project_id"`
+	ProviderID  string `json:"provider_id,omitempty"`
+}
+
+// DecideRequest is the body of POST /api/v1/decisions/{id}/decide
+type DecideRequest struct {
+	DeciderID string `json:"decider_id,omitempty"`
+	Decision  string `json:"decision"`
+	Rationale string `json:"rationale"`
+}
+
+// FileLockRequest is the body of POST /api/v1/file-locks
+type FileLockRequest struct {
+	FilePath  string `json:"file_path"`
+	ProjectID string `json:"project_id"`
+	AgentID   string `json:"agent_id"`
+	BeadID    string `json:"bead_id,omitempty"`
+}
+
+// StatusResponse acknowledges an operation that returns no resource
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// ErrorResponse is the body of every API error
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
@@ -4,10 +4,12 @@ import "time"
 
 // Lesson represents a learned insight from agent execution.
 // Lessons are per-project and injected into future prompts to avoid repeating mistakes.
+// Shared lessons have no project; they belong to an organization and go
+// into the prompts of all its projects.
 type Lesson struct {
 	ID             string    `json:"id"`
 	ProjectID      string    `json:"project_id"`
-	Category       string    `json:"category"` // compiler_error, test_failure, edit_failure, loop_pattern, conversation_insight
+	Category       string    `json:"category"` // compiler_error, test_failure, edit_failure, loop_pattern, conversation_insight, ci_failure
 	Title          string    `json:"title"`
 	Detail         string    `json:"detail"`
 	SourceBeadID   string    `json:"source_bead_id,omitempty"`
@@ -15,4 +17,61 @@ type Lesson struct {
 	CreatedAt      time.Time `json:"created_at"`
 	RelevanceScore float64   `json:"relevance_score"` // Decays over time
 	Embedding      []float32 `json:"-"`               // Vector embedding for semantic search (not serialized)
+	// EmbeddingVersion names the embedder Embedding came from; vectors of
+	// different versions are not comparable
+	EmbeddingVersion string `json:"embedding_version,omitempty"`
+	// SupersededBy is the lesson found to contradict this one; superseded
+	// lessons are kept but no longer injected into prompts
+	SupersededBy string     `json:"superseded_by,omitempty"`
+	SupersededAt *time.Time `json:"superseded_at,omitempty"`
+	// OrgID is the organization of a shared lesson
+	OrgID       string      `json:"org_id,omitempty"`
+	ShareStatus ShareStatus `json:"share_status,omitempty"`
+	// PromotedFrom is the project lesson a shared lesson was promoted from
+	PromotedFrom string `json:"promoted_from,omitempty"`
+}
+
+// ShareStatus is where a shared lesson is in the promotion workflow
+type ShareStatus string
+
+const (
+	// ShareStatusProposed lessons were promoted from a project and await
+	// approval before going into other projects' prompts
+	ShareStatusProposed ShareStatus = "proposed"
+	// ShareStatusShared lessons go into the prompts of the organization's
+	// projects that have not opted out
+	ShareStatusShared ShareStatus = "shared"
+)
+
+// LessonInjection records a lesson put into a dispatch's prompt and how
+// the dispatch ended
+type LessonInjection struct {
+	LessonID   string    `json:"lesson_id"`
+	DispatchID string    `json:"dispatch_id"`
+	ProjectID  string    `json:"project_id"`
+	BeadID     string    `json:"bead_id,omitempty"`
+	Outcome    string    `json:"outcome"` // The action loop's terminal reason
+	Succeeded  bool      `json:"succeeded"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// LessonEffectiveness is how the dispatches a lesson went into ended
+type LessonEffectiveness struct {
+	Lesson      *Lesson `json:"lesson"`
+	Injections  int     `json:"injections"`
+	Successes   int     `json:"successes"`
+	SuccessRate float64 `json:"success_rate"`
+	Lift        float64 `json:"lift"` // SuccessRate less the project's baseline
+}
+
+// LessonEffectivenessReport ranks a project's lessons by how much more or
+// less often the dispatches they went into succeeded than its dispatches
+// overall
+type LessonEffectivenessReport struct {
+	ProjectID           string                 `json:"project_id"`
+	Dispatches          int                    `json:"dispatches"` // Dispatches with lessons recorded
+	BaselineSuccessRate float64                `json:"baseline_success_rate"`
+	MinInjections       int                    `json:"min_injections"`
+	Helpful             []*LessonEffectiveness `json:"helpful"`
+	Useless             []*LessonEffectiveness `json:"useless"`
 }
@@ -17,6 +17,11 @@ type Persona struct {
 	Compatibility string                 `json:"compatibility,omitempty" yaml:"compatibility,omitempty"` // Environment requirements
 	Metadata      map[string]interface{} `json:"metadata,omitempty" yaml:"metadata,omitempty"`           // Flexible metadata
 
+	// Managed definition, published through the persona API
+	AllowedActions    []string `json:"allowed_actions,omitempty" yaml:"allowed_actions,omitempty"`       // Action types the persona's agents may run; empty allows all
+	ModelTier         string   `json:"model_tier,omitempty" yaml:"model_tier,omitempty"`                 // Preferred model tier: small, medium, large or xlarge
+	DefinitionVersion int      `json:"definition_version,omitempty" yaml:"definition_version,omitempty"` // Active published version, 0 when only SKILL.md defines it
+
 	// Deprecated fields (kept for backward compatibility during transition)
 	// TODO: Remove these after full migration
 	Character            string   `json:"character,omitempty" yaml:"character,omitempty"`                         // DEPRECATED: Use Instructions
@@ -129,6 +134,7 @@ type Project struct {
 	EntityMetadata `json:",inline"`
 
 	ID          string            `json:"id"`
+	OrgID       string            `json:"org_id,omitempty"` // Owning organization; empty is the default organization
 	Name        string            `json:"name"`
 	GitRepo     string            `json:"git_repo"`
 	Branch      string            `json:"branch"`
@@ -157,6 +163,46 @@ type Project struct {
 	LastSyncAt       *time.Time        `json:"last_sync_at,omitempty"`       // Last git pull/fetch
 	LastCommitHash   string            `json:"last_commit_hash,omitempty"`   // Last known commit SHA
 	GitConfigOptions map[string]string `json:"git_config_options,omitempty"` // Custom git config for this project
+
+	// Compliance constraints for regulated projects
+	Compliance *ComplianceConstraints `json:"compliance,omitempty"`
+
+	// SharedLessonsOptOut keeps the organization's shared lessons out of
+	// this project's prompts
+	SharedLessonsOptOut bool `json:"shared_lessons_opt_out,omitempty"`
+}
+
+// ComplianceConstraints restrict where a project's work may be processed and
+// what may be logged about it. A nil or zero value imposes nothing.
+type ComplianceConstraints struct {
+	RequiredProviderTags []string `json:"required_provider_tags,omitempty"` // Providers must carry every tag, e.g. "eu-hosted"
+	NoBodyLogging        bool     `json:"no_body_logging,omitempty"`        // Never log request or response bodies
+	RequireRedaction     bool     `json:"require_redaction,omitempty"`      // Redact sensitive data from everything logged
+}
+
+// Enabled reports whether any constraint is set
+func (c *ComplianceConstraints) Enabled() bool {
+	return c != nil && (len(c.RequiredProviderTags) > 0 || c.NoBodyLogging || c.RequireRedaction)
+}
+
+// AllowsProvider reports whether a provider carrying tags may serve the project
+func (c *ComplianceConstraints) AllowsProvider(tags []string) bool {
+	if c == nil {
+		return true
+	}
+	for _, required := range c.RequiredProviderTags {
+		found := false
+		for _, tag := range tags {
+			if tag == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
 }
 
 // VersionedEntity interface implementation for Project
@@ -256,8 +302,11 @@ type FileLock struct {
 	ProjectID string    `json:"project_id"`
 	AgentID   string    `json:"agent_id"`
 	BeadID    string    `json:"bead_id"`
+	Directory bool      `json:"directory,omitempty"` // Covers every file beneath FilePath
 	LockedAt  time.Time `json:"locked_at"`
 	ExpiresAt time.Time `json:"expires_at,omitempty"`
+	// StolenFrom is the agent whose expired lock this one replaced
+	StolenFrom string `json:"stolen_from,omitempty"`
 }
 
 // WorkGraph represents the dependency graph of beads
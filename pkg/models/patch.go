@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// PatchStatus is where a proposed patch stands
+type PatchStatus string
+
+const (
+	PatchStatusPending  PatchStatus = "pending"  // Waiting for a human
+	PatchStatusApproved PatchStatus = "approved" // A commit of the same diff goes through
+	PatchStatusRejected PatchStatus = "rejected" // The agent has to change its work
+)
+
+// ProposedPatch is the staged diff of an agent commit held for approval in
+// a project that requires commits to be approved
+type ProposedPatch struct {
+	ID           string      `json:"id"`
+	ProjectID    string      `json:"project_id"`
+	BeadID       string      `json:"bead_id,omitempty"`
+	AgentID      string      `json:"agent_id,omitempty"`
+	Message      string      `json:"message"`
+	Files        []string    `json:"files"`
+	Diff         string      `json:"diff"`
+	Fingerprint  string      `json:"fingerprint"`           // Hash of the diff; a commit of the same diff matches the patch
+	LowRisk      bool        `json:"low_risk"`              // Every file matches the project's low-risk globs
+	DecisionID   string      `json:"decision_id,omitempty"` // The decision that approves or rejects the patch too
+	Status       PatchStatus `json:"status"`
+	ReviewedBy   string      `json:"reviewed_by,omitempty"`
+	ReviewReason string      `json:"review_reason,omitempty"`
+	CreatedAt    time.Time   `json:"created_at"`
+	ReviewedAt   *time.Time  `json:"reviewed_at,omitempty"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// PlanStatus is where a bead's execution plan stands
+type PlanStatus string
+
+const (
+	PlanStatusProposed PlanStatus = "proposed" // Waiting for a human or the plan approval policy
+	PlanStatusApproved PlanStatus = "approved" // The real dispatch may run
+	PlanStatusRejected PlanStatus = "rejected" // The agent plans again, told why
+)
+
+// ExecutionPlan is what an agent dispatched in plan mode intends to do:
+// the files it will touch and the commands it will run, found by working
+// through the bead without performing any writes
+type ExecutionPlan struct {
+	Summary          string     `json:"summary"`
+	Steps            []string   `json:"steps,omitempty"`
+	Files            []string   `json:"files,omitempty"`
+	Commands         []string   `json:"commands,omitempty"`
+	EstimatedCostUSD float64    `json:"estimated_cost_usd"`
+	EstimatedTokens  int64      `json:"estimated_tokens,omitempty"`
+	PlanningTokens   int        `json:"planning_tokens,omitempty"` // What producing the plan took
+	AgentID          string     `json:"agent_id,omitempty"`
+	Status           PlanStatus `json:"status"`
+	ReviewedBy       string     `json:"reviewed_by,omitempty"` // A user, or "policy:<rule>" when auto-approved
+	ReviewReason     string     `json:"review_reason,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ReviewedAt       *time.Time `json:"reviewed_at,omitempty"`
+}
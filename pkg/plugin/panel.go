@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// CapabilityPanels is the custom capability a plugin declares when it serves
+// dashboard panels
+const CapabilityPanels = "panels"
+
+// panelIDPattern restricts panel IDs to URL path segments
+var panelIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Panel describes a dashboard panel a plugin contributes. The server exposes
+// the panel's data under the plugin's namespace, and dashboards use the
+// schema to decide how to render it.
+type Panel struct {
+	// ID identifies the panel within the plugin (e.g., "queue-depth")
+	ID string `json:"id" yaml:"id"`
+
+	// Title is the heading dashboards show above the panel
+	Title string `json:"title" yaml:"title"`
+
+	// Description explains what the panel shows
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Schema is the JSON Schema of the panel's data
+	Schema map[string]interface{} `json:"schema" yaml:"schema"`
+
+	// RefreshSeconds is how often dashboards should reload the data (0 = on demand)
+	RefreshSeconds int `json:"refresh_seconds,omitempty" yaml:"refresh_seconds,omitempty"`
+}
+
+// PanelData is the data of one panel at a point in time.
+type PanelData struct {
+	// Panel is the panel ID
+	Panel string `json:"panel"`
+
+	// Data is the panel's JSON data, which must match the panel's schema
+	Data interface{} `json:"data"`
+
+	// GeneratedAt is when the plugin produced the data
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// PanelPlugin is implemented by plugins that serve dashboard panels.
+// The panels themselves are declared in the plugin manifest.
+type PanelPlugin interface {
+	// GetPanelData returns the current data of a panel. Params are the query
+	// parameters of the dashboard's request, such as a project or time range.
+	GetPanelData(ctx context.Context, panelID string, params map[string]string) (*PanelData, error)
+}
+
+// Validate checks a panel declaration.
+func (p *Panel) Validate() error {
+	if !panelIDPattern.MatchString(p.ID) {
+		return fmt.Errorf("invalid panel id %q: must be lowercase letters, digits, - or _", p.ID)
+	}
+	if p.Title == "" {
+		return fmt.Errorf("panel %s: title is required", p.ID)
+	}
+	if len(p.Schema) == 0 {
+		return fmt.Errorf("panel %s: schema is required", p.ID)
+	}
+	if _, ok := p.Schema["type"].(string); !ok {
+		return fmt.Errorf("panel %s: schema must declare a type", p.ID)
+	}
+	if p.RefreshSeconds < 0 {
+		return fmt.Errorf("panel %s: refresh_seconds must not be negative", p.ID)
+	}
+	return nil
+}
+
+// ValidatePanelData checks decoded JSON data against a panel schema. It
+// supports the subset of JSON Schema dashboards rely on: type, properties,
+// required, items and enum.
+func ValidatePanelData(schema map[string]interface{}, data interface{}) error {
+	return validateSchema("data", schema, data)
+}
+
+func validateSchema(path string, schema map[string]interface{}, v interface{}) error {
+	if t, ok := schema["type"].(string); ok && !matchesType(t, v) {
+		return fmt.Errorf("%s: expected %s", path, t)
+	}
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			if fmt.Sprint(e) == fmt.Sprint(v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s: value %v is not one of %v", path, v, enum)
+		}
+	}
+
+	switch val := v.(type) {
+	case map[string]interface{}:
+		if required, ok := schema["required"].([]interface{}); ok {
+			for _, r := range required {
+				name, _ := r.(string)
+				if _, present := val[name]; !present {
+					return fmt.Errorf("%s: missing required property %q", path, name)
+				}
+			}
+		}
+		if props, ok := schema["properties"].(map[string]interface{}); ok {
+			for name, sub := range props {
+				subSchema, ok := sub.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if pv, present := val[name]; present {
+					if err := validateSchema(path+"."+name, subSchema, pv); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range val {
+				if err := validateSchema(fmt.Sprintf("%s[%d]", path, i), items, item); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// matchesType reports whether a decoded JSON value has the given JSON Schema type
+func matchesType(t string, v interface{}) bool {
+	switch t {
+	case "object":
+		_, ok := v.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := v.([]interface{})
+		return ok
+	case "string":
+		_, ok := v.(string)
+		return ok
+	case "boolean":
+		_, ok := v.(bool)
+		return ok
+	case "number":
+		_, ok := v.(float64)
+		return ok
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == float64(int64(f))
+	case "null":
+		return v == nil
+	}
+	return true
+}
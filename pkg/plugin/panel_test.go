@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPanelValidate(t *testing.T) {
+	valid := Panel{ID: "queue-depth", Title: "Queue depth", Schema: map[string]interface{}{"type": "number"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		panel Panel
+	}{
+		{"bad id", Panel{ID: "Queue Depth", Title: "t", Schema: map[string]interface{}{"type": "number"}}},
+		{"no title", Panel{ID: "q", Schema: map[string]interface{}{"type": "number"}}},
+		{"no schema", Panel{ID: "q", Title: "t"}},
+		{"untyped schema", Panel{ID: "q", Title: "t", Schema: map[string]interface{}{"properties": map[string]interface{}{}}}},
+		{"negative refresh", Panel{ID: "q", Title: "t", Schema: map[string]interface{}{"type": "number"}, RefreshSeconds: -1}},
+	}
+	for _, tt := range tests {
+		if err := tt.panel.Validate(); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
+
+func TestValidatePanelData(t *testing.T) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(`{
+		"type": "object",
+		"required": ["series"],
+		"properties": {
+			"unit": {"type": "string", "enum": ["usd", "tokens"]},
+			"series": {"type": "array", "items": {"type": "object", "required": ["t", "v"],
+				"properties": {"t": {"type": "string"}, "v": {"type": "number"}}}}
+		}
+	}`), &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"valid", `{"unit":"usd","series":[{"t":"2026-01-01","v":1.5}]}`, false},
+		{"missing required", `{"unit":"usd"}`, true},
+		{"wrong item type", `{"series":[{"t":"2026-01-01","v":"high"}]}`, true},
+		{"not in enum", `{"unit":"eur","series":[]}`, true},
+		{"not an object", `[1,2]`, true},
+	}
+	for _, tt := range tests {
+		var data interface{}
+		if err := json.Unmarshal([]byte(tt.data), &data); err != nil {
+			t.Fatal(err)
+		}
+		err := ValidatePanelData(schema, data)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: err = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -4,6 +4,15 @@ const REFRESH_INTERVAL = 5000; // 5 seconds
 
 const AUTH_TOKEN_KEY = 'loom.authToken';
 let authToken = localStorage.getItem(AUTH_TOKEN_KEY) || '';
+
+// SSO logins come back with the token in the URL fragment
+(function pickUpSSOToken() {
+    const match = window.location.hash.match(/[#&]sso_token=([^&]+)/);
+    if (!match) return;
+    authToken = decodeURIComponent(match[1]);
+    localStorage.setItem(AUTH_TOKEN_KEY, authToken);
+    history.replaceState(null, '', window.location.pathname + window.location.search);
+})();
 let authCheckInFlight = null;
 let loginInFlight = null;
 
@@ -582,19 +591,36 @@ async function ensureAuth(forcePrompt = false) {
 async function showLoginModal() {
     if (loginInFlight) return loginInFlight;
     loginInFlight = (async () => {
+        let sso = null;
+        try {
+            sso = await apiCall('/auth/oidc', { skipAuth: true, suppressToast: true });
+        } catch (err) {
+            // SSO is optional; fall back to password login.
+        }
+        const ssoEnabled = !!sso?.enabled;
+
         let loggedIn = false;
         while (!loggedIn) {
+            const fields = [
+                { id: 'username', label: 'Username', required: !ssoEnabled, placeholder: 'admin' },
+                { id: 'password', label: 'Password', required: !ssoEnabled, type: 'password', placeholder: 'Password' }
+            ];
+            if (ssoEnabled) {
+                fields.unshift({ id: 'sso', label: `Sign in with ${sso.provider}`, type: 'checkbox', value: true,
+                    description: 'Uncheck to sign in with a local username and password.' });
+            }
             const values = await formModal({
                 title: 'Sign in',
                 submitText: 'Sign in',
-                fields: [
-                    { id: 'username', label: 'Username', required: true, placeholder: 'admin' },
-                    { id: 'password', label: 'Password', required: true, type: 'password', placeholder: 'Password' }
-                ]
+                fields
             });
             if (!values) {
                 throw new Error('Login required');
             }
+            if (ssoEnabled && values.sso) {
+                window.location.href = sso.login_url;
+                return;
+            }
             try {
                 const resp = await apiCall('/auth/login', {
                     method: 'POST',
@@ -657,6 +683,9 @@ function startEventStream() {
             'bead.assigned': ['beads', 'agents', 'status'],
             'bead.status_change': ['beads', 'status'],
             'bead.completed': ['beads', 'status'],
+            'bead.handed_off': ['beads', 'agents', 'status'],
+            'bead.verification_failed': ['beads', 'status'],
+            'bead.ci_status': ['beads'],
             'agent.spawned': ['agents', 'projects', 'status'],
             'agent.status_change': ['agents', 'status'],
             'agent.heartbeat': ['agents', 'status'],
@@ -2895,6 +2924,11 @@ async function showRegisterProviderModal(preset = {}) {
                     { value: 'openai', label: 'OpenAI' },
                     { value: 'anthropic', label: 'Anthropic' },
                     { value: 'ollama', label: 'Ollama' },
+                    { value: 'azure', label: 'Azure OpenAI' },
+                    { value: 'bedrock', label: 'AWS Bedrock' },
+                    { value: 'gemini', label: 'Google Gemini' },
+                    { value: 'vllm', label: 'vLLM' },
+                    { value: 'tgi', label: 'HuggingFace TGI' },
                     { value: 'custom', label: 'Custom' }
                 ],
                 value: preset.type || 'local'
@@ -3005,6 +3039,11 @@ async function showEditProviderModal(providerId) {
                         { value: 'openai', label: 'OpenAI' },
                         { value: 'anthropic', label: 'Anthropic' },
                         { value: 'ollama', label: 'Ollama' },
+                        { value: 'azure', label: 'Azure OpenAI' },
+                        { value: 'bedrock', label: 'AWS Bedrock' },
+                        { value: 'gemini', label: 'Google Gemini' },
+                        { value: 'vllm', label: 'vLLM' },
+                        { value: 'tgi', label: 'HuggingFace TGI' },
                         { value: 'custom', label: 'Custom' }
                     ]
                 },
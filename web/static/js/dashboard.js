@@ -0,0 +1,411 @@
+// Live Dashboard JavaScript
+//
+// Shows beads, pending decisions, notifications, activity and provider
+// health, kept current by the activity, notification and event streams.
+// Streams are read with fetch rather than EventSource so the bearer token
+// the main UI stores can be sent with them.
+
+const API_BASE = '/api/v1';
+const AUTH_TOKEN_KEY = 'loom.authToken';
+const MAX_ACTIVITY = 100;
+const MAX_NOTIFICATIONS = 50;
+const MAX_BEADS = 50;
+const RECONNECT_DELAY_MAX = 30000;
+
+const state = {
+    beads: [],
+    decisions: [],
+    notifications: [],
+    unread: null,
+    activity: [],
+    providers: []
+};
+
+const streams = {};
+const reloadTimers = {};
+
+function authHeaders() {
+    const token = localStorage.getItem(AUTH_TOKEN_KEY);
+    return token ? { Authorization: `Bearer ${token}` } : {};
+}
+
+async function api(endpoint, options = {}) {
+    const response = await fetch(`${API_BASE}${endpoint}`, {
+        ...options,
+        headers: { 'Content-Type': 'application/json', ...authHeaders(), ...options.headers }
+    });
+    if (!response.ok) {
+        let message = `${response.status} ${response.statusText}`;
+        try {
+            const body = await response.json();
+            message = body.error || message;
+        } catch {
+            // keep the status line
+        }
+        throw new Error(message);
+    }
+    if (response.status === 204) return null;
+    return response.json();
+}
+
+function escapeHtml(value) {
+    return String(value ?? '')
+        .replace(/&/g, '&amp;')
+        .replace(/</g, '&lt;')
+        .replace(/>/g, '&gt;')
+        .replace(/"/g, '&quot;')
+        .replace(/'/g, '&#39;');
+}
+
+function timeAgo(value) {
+    if (!value) return '';
+    const then = new Date(value);
+    if (isNaN(then) || then.getFullYear() < 2000) return '';
+    const seconds = Math.max(0, Math.round((Date.now() - then) / 1000));
+    if (seconds < 60) return `${seconds}s ago`;
+    if (seconds < 3600) return `${Math.floor(seconds / 60)}m ago`;
+    if (seconds < 86400) return `${Math.floor(seconds / 3600)}h ago`;
+    return then.toLocaleString();
+}
+
+function showError(listId, error) {
+    document.getElementById(listId).innerHTML = `<div class="empty">${escapeHtml(error.message)}</div>`;
+}
+
+// ----- Streams -----
+
+function setStreamStatus() {
+    const pill = document.getElementById('stream-status');
+    const names = Object.keys(streams);
+    const live = names.filter((name) => streams[name].live).length;
+    pill.classList.toggle('live', live === names.length);
+    pill.classList.toggle('down', live === 0);
+    pill.textContent = live === names.length ? 'Live' : `Live ${live}/${names.length} streams`;
+}
+
+// stream reads a server-sent event stream, calling handlers[event] with the
+// parsed data of each event, and reconnects with backoff when it drops.
+// handlers.connected, when given, runs on every (re)connect.
+function stream(name, endpoint, handlers) {
+    const s = { live: false, delay: 1000 };
+    streams[name] = s;
+
+    const connect = async () => {
+        try {
+            const response = await fetch(`${API_BASE}${endpoint}`, {
+                headers: { Accept: 'text/event-stream', ...authHeaders() }
+            });
+            if (!response.ok || !response.body) throw new Error(`${response.status}`);
+
+            const reader = response.body.getReader();
+            const decoder = new TextDecoder();
+            let buffer = '';
+            for (;;) {
+                const { value, done } = await reader.read();
+                if (done) break;
+                buffer += decoder.decode(value, { stream: true });
+
+                let end;
+                while ((end = buffer.indexOf('\n\n')) >= 0) {
+                    const block = buffer.slice(0, end);
+                    buffer = buffer.slice(end + 2);
+                    let event = 'message';
+                    const data = [];
+                    for (const line of block.split('\n')) {
+                        if (line.startsWith('event:')) event = line.slice(6).trim();
+                        else if (line.startsWith('data:')) data.push(line.slice(5).trim());
+                    }
+                    if (event === 'connected') {
+                        s.live = true;
+                        s.delay = 1000;
+                        setStreamStatus();
+                        if (handlers.connected) handlers.connected();
+                        continue;
+                    }
+                    const handler = handlers[event];
+                    if (!handler || data.length === 0) continue;
+                    try {
+                        handler(JSON.parse(data.join('\n')));
+                    } catch {
+                        // skip malformed events
+                    }
+                }
+            }
+        } catch {
+            // reconnect below
+        }
+        s.live = false;
+        setStreamStatus();
+        setTimeout(connect, s.delay);
+        s.delay = Math.min(s.delay * 2, RECONNECT_DELAY_MAX);
+    };
+    connect();
+}
+
+// scheduleReload coalesces bursts of events into one reload per panel
+function scheduleReload(kind, delayMs = 500) {
+    if (reloadTimers[kind]) return;
+    reloadTimers[kind] = setTimeout(async () => {
+        delete reloadTimers[kind];
+        await loaders[kind]();
+    }, delayMs);
+}
+
+// ----- Beads -----
+
+async function loadBeads() {
+    try {
+        const beads = await api('/beads');
+        state.beads = Array.isArray(beads) ? beads : [];
+        renderBeads();
+    } catch (error) {
+        showError('beads-list', error);
+    }
+}
+
+function renderBeads() {
+    const counts = {};
+    for (const bead of state.beads) counts[bead.status] = (counts[bead.status] || 0) + 1;
+    document.getElementById('beads-summary').innerHTML = ['open', 'in_progress', 'blocked', 'closed']
+        .map((status) => `<span class="summary-chip">${escapeHtml(status.replace('_', ' '))}: ${counts[status] || 0}</span>`)
+        .join('');
+
+    const active = state.beads
+        .filter((bead) => bead.status !== 'closed')
+        .sort((a, b) => (a.priority - b.priority) || (new Date(b.updated_at) - new Date(a.updated_at)));
+    document.getElementById('beads-count').textContent = `${active.length} active`;
+
+    const list = document.getElementById('beads-list');
+    if (active.length === 0) {
+        list.innerHTML = '<div class="empty">No active beads</div>';
+        return;
+    }
+    list.innerHTML = active.slice(0, MAX_BEADS).map((bead) => `
+        <div class="row">
+            <span class="badge p${escapeHtml(bead.priority)}">P${escapeHtml(bead.priority)}</span>
+            <span class="badge">${escapeHtml(bead.status)}</span>
+            ${escapeHtml(bead.title)}
+            <div class="row-meta">${escapeHtml(bead.id)} · ${escapeHtml(bead.project_id)}${bead.assigned_to ? ` · ${escapeHtml(bead.assigned_to)}` : ''} · ${escapeHtml(timeAgo(bead.updated_at))}</div>
+        </div>`).join('');
+}
+
+// ----- Decisions -----
+
+async function loadDecisions() {
+    try {
+        const decisions = await api('/decisions');
+        state.decisions = (Array.isArray(decisions) ? decisions : []).filter((d) => !d.decision && d.status !== 'closed');
+        renderDecisions();
+    } catch (error) {
+        showError('decisions-list', error);
+    }
+}
+
+function renderDecisions() {
+    document.getElementById('decisions-count').textContent = state.decisions.length || '';
+    const list = document.getElementById('decisions-list');
+    if (state.decisions.length === 0) {
+        list.innerHTML = '<div class="empty">No pending decisions</div>';
+        return;
+    }
+    list.innerHTML = state.decisions.map((d) => `
+        <div class="row">
+            <span class="badge p${escapeHtml(d.priority)}">P${escapeHtml(d.priority)}</span>
+            ${escapeHtml(d.question || d.title)}
+            <div class="row-meta">${escapeHtml(d.id)}${d.project_id ? ` · ${escapeHtml(d.project_id)}` : ''}${d.recommendation ? ` · recommends ${escapeHtml(d.recommendation)}` : ''}</div>
+            <div class="decision-options">
+                ${(d.options || []).map((option) => `<button type="button" class="secondary" data-decision="${escapeHtml(d.id)}" data-option="${escapeHtml(option)}">${escapeHtml(option)}</button>`).join('')}
+            </div>
+        </div>`).join('');
+}
+
+async function decide(decisionId, option) {
+    const rationale = window.prompt(`Rationale for "${option}"`);
+    if (rationale === null) return;
+    try {
+        await api(`/decisions/${encodeURIComponent(decisionId)}/decide`, {
+            method: 'POST',
+            body: JSON.stringify({ decision: option, rationale })
+        });
+        await loadDecisions();
+    } catch (error) {
+        window.alert(`Decision failed: ${error.message}`);
+    }
+}
+
+// ----- Notifications -----
+
+async function loadNotifications() {
+    try {
+        const [page, unread] = await Promise.all([
+            api(`/notifications?limit=${MAX_NOTIFICATIONS}`),
+            api('/notifications/unread-counts')
+        ]);
+        state.notifications = page.notifications || [];
+        state.unread = unread;
+        renderNotifications();
+    } catch (error) {
+        showError('notifications-list', error);
+    }
+}
+
+function applyUnreadDeltas(deltas) {
+    if (!state.unread) return;
+    for (const d of deltas || []) {
+        state.unread.total = Math.max(0, state.unread.total + d.delta);
+    }
+    renderNotificationCount();
+}
+
+function renderNotificationCount() {
+    const total = state.unread ? state.unread.total : 0;
+    document.getElementById('notifications-count').textContent = total ? `${total} unread` : '';
+}
+
+function renderNotifications(freshId) {
+    renderNotificationCount();
+    const list = document.getElementById('notifications-list');
+    if (state.notifications.length === 0) {
+        list.innerHTML = '<div class="empty">No notifications</div>';
+        return;
+    }
+    list.innerHTML = state.notifications.map((n) => `
+        <div class="row${n.status === 'unread' ? ' unread' : ''}${n.id === freshId ? ' fresh' : ''}">
+            <span class="badge ${escapeHtml(n.priority)}">${escapeHtml(n.priority)}</span>
+            ${n.link ? `<a href="${escapeHtml(n.link)}">${escapeHtml(n.title)}</a>` : escapeHtml(n.title)}
+            <div class="row-meta">${escapeHtml(n.message)}</div>
+            <div class="row-meta">${escapeHtml(n.event_type)} · ${escapeHtml(timeAgo(n.created_at))}</div>
+        </div>`).join('');
+}
+
+// ----- Activity -----
+
+async function loadActivity() {
+    try {
+        const page = await api(`/activity-feed?limit=${MAX_ACTIVITY}`);
+        state.activity = page.activities || [];
+        renderActivity();
+    } catch (error) {
+        showError('activity-list', error);
+    }
+}
+
+function describeActivity(a) {
+    if (a.summary) return a.summary;
+    const actor = a.actor_id || a.source || 'system';
+    const resource = a.resource_title || a.resource_id || a.resource_type;
+    return `${actor} ${a.action} ${resource}`;
+}
+
+function renderActivity(freshId) {
+    const list = document.getElementById('activity-list');
+    if (state.activity.length === 0) {
+        list.innerHTML = '<div class="empty">No activity yet</div>';
+        return;
+    }
+    list.innerHTML = state.activity.map((a) => `
+        <div class="row${a.id === freshId ? ' fresh' : ''}">
+            ${escapeHtml(describeActivity(a))}
+            ${a.aggregation_count > 1 ? `<span class="badge">×${escapeHtml(a.aggregation_count)}</span>` : ''}
+            <div class="row-meta">${escapeHtml(a.event_type)}${a.project_id ? ` · ${escapeHtml(a.project_id)}` : ''} · ${escapeHtml(timeAgo(a.last_event_at || a.timestamp))}</div>
+        </div>`).join('');
+}
+
+// ----- Providers -----
+
+async function loadProviders() {
+    try {
+        const providers = await api('/providers');
+        state.providers = Array.isArray(providers) ? providers : [];
+        renderProviders();
+    } catch (error) {
+        showError('providers-list', error);
+    }
+}
+
+function providerHealth(p) {
+    if (p.status === 'healthy' || p.status === 'active') return 'healthy';
+    if (p.status === 'pending') return 'pending';
+    if (p.last_heartbeat_error) return 'unhealthy';
+    return 'degraded';
+}
+
+function renderProviders() {
+    const healthy = state.providers.filter((p) => providerHealth(p) === 'healthy').length;
+    document.getElementById('providers-count').textContent = state.providers.length ? `${healthy}/${state.providers.length} healthy` : '';
+    const list = document.getElementById('providers-list');
+    if (state.providers.length === 0) {
+        list.innerHTML = '<div class="empty">No providers registered</div>';
+        return;
+    }
+    list.innerHTML = state.providers.map((p) => {
+        const health = providerHealth(p);
+        const latency = p.last_heartbeat_latency_ms ? ` · ${escapeHtml(p.last_heartbeat_latency_ms)}ms` : '';
+        return `
+        <div class="row">
+            <span class="badge ${health}">${escapeHtml(p.status || health)}</span>
+            ${escapeHtml(p.name || p.id)}
+            <div class="row-meta">${escapeHtml(p.type)} · ${escapeHtml(p.selected_model || p.model)}${latency} · heartbeat ${escapeHtml(timeAgo(p.last_heartbeat_at) || 'never')}</div>
+            ${p.last_heartbeat_error ? `<div class="row-meta">${escapeHtml(p.last_heartbeat_error)}</div>` : ''}
+        </div>`;
+    }).join('');
+}
+
+// ----- Startup -----
+
+const loaders = {
+    beads: loadBeads,
+    decisions: loadDecisions,
+    notifications: loadNotifications,
+    activity: loadActivity,
+    providers: loadProviders
+};
+
+// Events on the event bus that change what a panel shows
+const eventReloads = {
+    'bead.created': ['beads'],
+    'bead.assigned': ['beads'],
+    'bead.status_change': ['beads'],
+    'bead.completed': ['beads'],
+    'bead.handed_off': ['beads'],
+    'decision.created': ['decisions'],
+    'decision.resolved': ['decisions'],
+    'provider.registered': ['providers'],
+    'provider.updated': ['providers'],
+    'provider.deleted': ['providers']
+};
+
+document.getElementById('decisions-list').addEventListener('click', (event) => {
+    const button = event.target.closest('button[data-decision]');
+    if (button) decide(button.dataset.decision, button.dataset.option);
+});
+
+Object.values(loaders).forEach((load) => load());
+
+stream('activity', '/activity-feed/stream', {
+    activity: (a) => {
+        state.activity = [a, ...state.activity.filter((x) => x.id !== a.id)].slice(0, MAX_ACTIVITY);
+        renderActivity(a.id);
+    }
+});
+
+stream('notifications', '/notifications/stream', {
+    // Count deltas missed while disconnected are not resent
+    connected: () => scheduleReload('notifications', 0),
+    notification: (n) => {
+        state.notifications = [n, ...state.notifications.filter((x) => x.id !== n.id)].slice(0, MAX_NOTIFICATIONS);
+        renderNotifications(n.id);
+    },
+    unread_counts: (body) => applyUnreadDeltas(body.deltas)
+});
+
+stream('events', '/events/stream', Object.fromEntries(
+    Object.entries(eventReloads).map(([type, kinds]) => [type, () => kinds.forEach((kind) => scheduleReload(kind))])
+));
+
+// Heartbeats do not publish events, so provider health is also polled
+setInterval(() => scheduleReload('providers', 0), 60000);
+setInterval(() => {
+    renderBeads();
+    renderActivity();
+}, 30000);
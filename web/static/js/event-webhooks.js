@@ -0,0 +1,198 @@
+// Event Webhooks View
+// Outbound webhook subscriptions and their delivery log
+
+let selectedEventWebhookId = null;
+
+async function renderEventWebhooks() {
+    const container = document.getElementById('event-webhooks-container');
+    if (!container) return;
+
+    try {
+        const hooks = await apiCall('/event-webhooks');
+
+        if (!hooks || hooks.length === 0) {
+            container.innerHTML = renderEmptyState(
+                'No event webhooks',
+                'Subscribe an external endpoint to activity events such as bead.closed or workflow.failed'
+            );
+            return;
+        }
+
+        container.innerHTML = `
+            <table class="data-table" style="margin-top: 1rem;">
+                <thead>
+                    <tr>
+                        <th>Name</th>
+                        <th>URL</th>
+                        <th>Events</th>
+                        <th>Projects</th>
+                        <th>Status</th>
+                        <th>Actions</th>
+                    </tr>
+                </thead>
+                <tbody>
+                    ${hooks.map(hook => renderEventWebhookRow(hook)).join('')}
+                </tbody>
+            </table>
+        `;
+    } catch (error) {
+        container.innerHTML = '<div class="error">Failed to load event webhooks: ' + escapeHtml(error.message) + '</div>';
+    }
+}
+
+function renderEventWebhookRow(hook) {
+    const id = escapeHtml(hook.id);
+    const status = hook.enabled ? '<span style="color: var(--success-color);">Enabled</span>' :
+                   '<span style="color: var(--text-muted);">Disabled</span>';
+
+    return `
+        <tr>
+            <td>${escapeHtml(hook.name || '')}</td>
+            <td><code class="code-small">${escapeHtml(hook.url || '')}</code></td>
+            <td class="small">${escapeHtml((hook.event_types || []).join(', '))}</td>
+            <td class="small">${escapeHtml((hook.project_ids || []).join(', ') || 'All')}</td>
+            <td>${status}</td>
+            <td>
+                <button type="button" class="secondary" onclick="showWebhookDeliveries('${id}')">Deliveries</button>
+                <button type="button" class="secondary" onclick="testEventWebhook('${id}')">Test</button>
+                <button type="button" class="secondary" onclick="toggleEventWebhook('${id}', ${!hook.enabled})">${hook.enabled ? 'Disable' : 'Enable'}</button>
+                <button type="button" class="danger" onclick="deleteEventWebhook('${id}')">Delete</button>
+            </td>
+        </tr>
+    `;
+}
+
+async function createEventWebhook(event) {
+    event.preventDefault();
+    const split = value => value.split(',').map(s => s.trim()).filter(Boolean);
+    const url = document.getElementById('event-webhook-url').value.trim();
+    const eventTypes = split(document.getElementById('event-webhook-events').value);
+    const projectIds = split(document.getElementById('event-webhook-projects').value);
+
+    try {
+        const hook = await apiCall('/event-webhooks', {
+            method: 'POST',
+            body: JSON.stringify({ url, event_types: eventTypes, project_ids: projectIds }),
+            skipAutoFile: true
+        });
+        event.target.reset();
+        showToast('Webhook created. Signing secret (shown once): ' + hook.secret, 'success', 30000);
+        renderEventWebhooks();
+    } catch (error) {
+        showToast('Failed to create webhook: ' + error.message, 'error');
+    }
+}
+
+async function testEventWebhook(id) {
+    try {
+        const delivery = await apiCall(`/event-webhooks/${encodeURIComponent(id)}/test`, { method: 'POST', skipAutoFile: true });
+        const ok = delivery.status === 'delivered';
+        showToast(ok ? 'Ping delivered' : 'Ping failed: ' + (delivery.error || delivery.status), ok ? 'success' : 'error');
+        if (selectedEventWebhookId === id) showWebhookDeliveries(id);
+    } catch (error) {
+        showToast('Failed to send ping: ' + error.message, 'error');
+    }
+}
+
+async function toggleEventWebhook(id, enabled) {
+    try {
+        await apiCall(`/event-webhooks/${encodeURIComponent(id)}`, {
+            method: 'PUT',
+            body: JSON.stringify({ enabled })
+        });
+        renderEventWebhooks();
+    } catch (error) {
+        showToast('Failed to update webhook: ' + error.message, 'error');
+    }
+}
+
+async function deleteEventWebhook(id) {
+    if (!confirm('Delete this webhook and its delivery log?')) return;
+    try {
+        await apiCall(`/event-webhooks/${encodeURIComponent(id)}`, { method: 'DELETE' });
+        if (selectedEventWebhookId === id) {
+            selectedEventWebhookId = null;
+            document.getElementById('webhook-deliveries-container').innerHTML = '';
+        }
+        renderEventWebhooks();
+    } catch (error) {
+        showToast('Failed to delete webhook: ' + error.message, 'error');
+    }
+}
+
+async function showWebhookDeliveries(id) {
+    const container = document.getElementById('webhook-deliveries-container');
+    if (!container) return;
+    selectedEventWebhookId = id;
+
+    try {
+        const page = await apiCall(`/event-webhooks/${encodeURIComponent(id)}/deliveries?limit=50`);
+        const deliveries = page.deliveries || [];
+
+        if (deliveries.length === 0) {
+            container.innerHTML = renderEmptyState('No deliveries yet', 'Deliveries appear here as matching events occur');
+            return;
+        }
+
+        container.innerHTML = `
+            <h4 style="margin-top: 1rem;">Recent deliveries (${page.total})</h4>
+            <table class="data-table">
+                <thead>
+                    <tr>
+                        <th>Created</th>
+                        <th>Event</th>
+                        <th>Status</th>
+                        <th>Attempts</th>
+                        <th>Response</th>
+                        <th>Next Attempt</th>
+                        <th></th>
+                    </tr>
+                </thead>
+                <tbody>
+                    ${deliveries.map(d => renderWebhookDeliveryRow(id, d)).join('')}
+                </tbody>
+            </table>
+        `;
+    } catch (error) {
+        container.innerHTML = '<div class="error">Failed to load deliveries: ' + escapeHtml(error.message) + '</div>';
+    }
+}
+
+function renderWebhookDeliveryRow(hookId, d) {
+    const colors = { delivered: 'var(--success-color)', failed: 'var(--danger-color)', pending: 'var(--warning-color)' };
+    const response = d.error ? escapeHtml(d.error) : (d.response_code ? String(d.response_code) : '');
+
+    return `
+        <tr>
+            <td class="small">${new Date(d.created_at).toLocaleString()}</td>
+            <td><span class="badge">${escapeHtml(d.event_type)}</span></td>
+            <td><span style="color: ${colors[d.status] || 'inherit'};">${escapeHtml(d.status)}</span></td>
+            <td>${d.attempts}</td>
+            <td class="small">${response}</td>
+            <td class="small">${d.next_attempt_at ? new Date(d.next_attempt_at).toLocaleString() : ''}</td>
+            <td><button type="button" class="secondary" onclick="redeliverWebhook('${escapeHtml(hookId)}', '${escapeHtml(d.id)}')">Redeliver</button></td>
+        </tr>
+    `;
+}
+
+async function redeliverWebhook(hookId, deliveryId) {
+    try {
+        const path = `/event-webhooks/${encodeURIComponent(hookId)}/deliveries/${encodeURIComponent(deliveryId)}/redeliver`;
+        const delivery = await apiCall(path, { method: 'POST', skipAutoFile: true });
+        showToast('Redelivery ' + delivery.status, delivery.status === 'delivered' ? 'success' : 'error');
+        showWebhookDeliveries(hookId);
+    } catch (error) {
+        showToast('Failed to redeliver: ' + error.message, 'error');
+    }
+}
+
+document.addEventListener('DOMContentLoaded', function() {
+    var refreshBtn = document.getElementById('refresh-event-webhooks-btn');
+    if (refreshBtn) {
+        refreshBtn.addEventListener('click', renderEventWebhooks);
+    }
+    var form = document.getElementById('event-webhook-form');
+    if (form) {
+        form.addEventListener('submit', createEventWebhook);
+    }
+});
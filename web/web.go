@@ -0,0 +1,21 @@
+// Package web embeds the web UI so the server binary can serve it without
+// a separate frontend deployment.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed static
+var files embed.FS
+
+// Static returns the web UI's files, rooted at web/static
+func Static() fs.FS {
+	static, err := fs.Sub(files, "static")
+	if err != nil {
+		// Only possible if the embed directive above changes
+		panic(err)
+	}
+	return static
+}